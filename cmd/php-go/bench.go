@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krizos/php-go/pkg/bench"
+)
+
+// Defaults of php-go bench
+const (
+	defaultBenchIterations = 10
+	defaultBenchWarmup     = 2
+)
+
+// handleBench times the standard benchmarks, or the scripts given:
+// "php-go bench -n 20 --filter=micro/ --baseline=bench.json". With a
+// baseline the exit status is 1 if a benchmark regressed or failed, so
+// CI can run it; --save records the run as the next baseline.
+func handleBench(args []string) {
+	opts := bench.Options{Iterations: defaultBenchIterations, Warmup: defaultBenchWarmup}
+	threshold := bench.DefaultThreshold
+	var patterns, paths []string
+	var baselinePath, savePath string
+	list := false
+
+	// Parse flags
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var err error
+		if optimization, ok := optimizationFlag(arg); ok {
+			opts.Level = optimization
+		} else if arg == "--list" {
+			list = true
+		} else if arg == "-n" && i+1 < len(args) {
+			i++
+			opts.Iterations, err = strconv.Atoi(args[i])
+			if err == nil && opts.Iterations <= 0 {
+				err = fmt.Errorf("not positive")
+			}
+		} else if strings.HasPrefix(arg, "--warmup=") {
+			opts.Warmup, err = strconv.Atoi(strings.TrimPrefix(arg, "--warmup="))
+			if err == nil && opts.Warmup < 0 {
+				err = fmt.Errorf("negative")
+			}
+		} else if strings.HasPrefix(arg, "--threshold=") {
+			threshold, err = parseThreshold(strings.TrimPrefix(arg, "--threshold="))
+		} else if strings.HasPrefix(arg, "--filter=") {
+			patterns = append(patterns, strings.Split(strings.TrimPrefix(arg, "--filter="), ",")...)
+		} else if strings.HasPrefix(arg, "--baseline=") {
+			baselinePath = strings.TrimPrefix(arg, "--baseline=")
+		} else if strings.HasPrefix(arg, "--save=") {
+			savePath = strings.TrimPrefix(arg, "--save=")
+		} else if strings.HasPrefix(arg, "-") {
+			fmt.Fprintf(os.Stderr, "Error: unknown bench option '%s'\n", arg)
+			os.Exit(1)
+		} else {
+			paths = append(paths, arg)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid %s value\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	benchmarks := bench.Standard()
+	if len(paths) > 0 {
+		var err error
+		if benchmarks, err = bench.Load(paths); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	benchmarks = bench.Filter(benchmarks, patterns)
+	if list {
+		for _, b := range benchmarks {
+			fmt.Println(b.Name)
+		}
+		return
+	}
+	if len(benchmarks) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no benchmarks to run")
+		os.Exit(1)
+	}
+
+	var baseline *bench.Report
+	if baselinePath != "" {
+		var err error
+		if baseline, err = bench.ReadReport(baselinePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading the baseline: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("%d benchmark(s), %d run(s) each after %d warmup run(s)\n\n", len(benchmarks), opts.Iterations, opts.Warmup)
+	fmt.Printf("%-28s %12s %12s %12s\n", "benchmark", "mean", "stddev", "min")
+	var results []bench.Result
+	failed := 0
+	for _, b := range benchmarks {
+		result := bench.Run(ctx, b, opts)
+		results = append(results, result)
+		if result.Error != "" {
+			failed++
+			fmt.Printf("%-28s FAIL: %s\n", result.Name, result.Error)
+		} else {
+			fmt.Printf("%-28s %12s %12s %12s\n", result.Name, formatDuration(result.Mean), "±"+formatDuration(result.StdDev), formatDuration(result.Min))
+		}
+		if ctx.Err() != nil {
+			fmt.Fprintln(os.Stderr, "Interrupted")
+			os.Exit(1)
+		}
+	}
+
+	if savePath != "" {
+		if err := bench.NewReport(opts, results).Save(savePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing the results: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	regressions := 0
+	if baseline != nil {
+		fmt.Printf("\nCompared with %s (threshold %.0f%%)\n", baselinePath, threshold*100)
+		fmt.Printf("%-28s %12s %12s %9s\n", "benchmark", "baseline", "current", "change")
+		for _, c := range bench.Compare(baseline, results, threshold) {
+			marker := ""
+			if c.Regression {
+				regressions++
+				marker = "  REGRESSION"
+			}
+			fmt.Printf("%-28s %12s %12s %+8.1f%%%s\n", c.Name, formatDuration(c.Baseline.Mean), formatDuration(c.Current.Mean), c.Change*100, marker)
+		}
+		fmt.Printf("\n%d regression(s), %d failure(s)\n", regressions, failed)
+		if regressions > 0 || failed > 0 {
+			os.Exit(1)
+		}
+	}
+}
+
+// parseThreshold parses a slowdown threshold, a percentage ("10%") or a
+// fraction ("0.1")
+func parseThreshold(value string) (float64, error) {
+	percent, isPercent := strings.CutSuffix(value, "%")
+	n, err := strconv.ParseFloat(percent, 64)
+	if isPercent {
+		n /= 100
+	}
+	if err == nil && n < 0 {
+		err = fmt.Errorf("negative threshold")
+	}
+	return n, err
+}
+
+// formatDuration shows a timing with three significant digits
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return fmt.Sprintf("%.3gs", d.Seconds())
+	case d >= time.Millisecond:
+		return fmt.Sprintf("%.3gms", float64(d)/float64(time.Millisecond))
+	case d >= time.Microsecond:
+		return fmt.Sprintf("%.3gµs", float64(d)/float64(time.Microsecond))
+	}
+	return fmt.Sprintf("%dns", d.Nanoseconds())
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/krizos/php-go/pkg/bundle"
+	"github.com/krizos/php-go/pkg/compiler"
+)
+
+// handleBundle packs an entry script and the files it includes into a
+// bundle: "php-go bundle -o app.bundle index.php". Includes of computed
+// paths are reported; their files are added with --include.
+func handleBundle(args []string) {
+	var entry, output string
+	var opts bundle.Options
+
+	// Parse flags
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if optimization, ok := optimizationFlag(arg); ok {
+			opts.OptimizationLevel = optimization
+		} else if (arg == "-o" || arg == "--include") && i+1 < len(args) {
+			i++
+			if arg == "-o" {
+				output = args[i]
+			} else {
+				opts.Extra = append(opts.Extra, args[i])
+			}
+		} else if arg == "--compile" {
+			opts.Compile = true
+		} else if entry == "" {
+			entry = arg
+		}
+	}
+
+	if entry == "" {
+		fmt.Fprintln(os.Stderr, "Error: no file specified")
+		os.Exit(1)
+	}
+	if output == "" {
+		fmt.Fprintln(os.Stderr, "Error: no output file specified (-o)")
+		os.Exit(1)
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating bundle: %v\n", err)
+		os.Exit(1)
+	}
+	manifest, warnings, err := bundle.Create(file, entry, opts)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output)
+		fmt.Fprintf(os.Stderr, "Error creating bundle: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	fmt.Printf("Bundled %d file(s) into %s (entry %s", len(manifest.Files), output, manifest.Entry)
+	if manifest.Compiled {
+		fmt.Printf(", compiled at -O%d", optimizationNumber(manifest.OptimizationLevel))
+	}
+	fmt.Println(")")
+}
+
+// optimizationNumber returns the number of the -O flag selecting an
+// optimization level
+func optimizationNumber(level compiler.OptimizationLevel) int {
+	switch level {
+	case compiler.OptimizePeephole:
+		return 1
+	case compiler.OptimizeDataFlow:
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/compiler"
+	varfuncs "github.com/krizos/php-go/pkg/stdlib/var"
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// errDebugQuit aborts the script when the user quits the debugger
+var errDebugQuit = errors.New("debugger quit")
+
+// handleDebug runs a script under the interactive debugger: "php-go debug
+// app.php". It stops on the first line for breakpoints to be set.
+func handleDebug(args []string) {
+	var filePath string
+	iniFile := defaultIniFile
+	noIniFile := false
+	var directives []string
+	level := compiler.OptimizeNone
+
+	// Parse flags
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if optimization, ok := optimizationFlag(arg); ok {
+			level = optimization
+		} else if (arg == "-c" || arg == "-d") && i+1 < len(args) {
+			i++
+			if arg == "-c" {
+				iniFile = args[i]
+			} else {
+				directives = append(directives, args[i])
+			}
+		} else if strings.HasPrefix(arg, "-d") && len(arg) > 2 {
+			directives = append(directives, arg[2:])
+		} else if arg == "-n" {
+			noIniFile = true
+		} else if filePath == "" {
+			filePath = arg
+		}
+	}
+
+	if filePath == "" {
+		fmt.Fprintln(os.Stderr, "Error: no file specified")
+		os.Exit(1)
+	}
+
+	script := compileFile(filePath, level, false)
+	machine := vm.New()
+	machine.SetScriptCompiler(compiler.ScriptCompiler(level))
+	configure(machine.Config(), iniFile, noIniFile, directives)
+	// The output is shown as it is produced, between the prompts
+	machine.SetOutputWriter(os.Stdout)
+
+	console := &debugConsole{in: bufio.NewReader(os.Stdin), out: os.Stdout, script: filePath, sources: map[string][]string{}}
+	vm.NewDebugger(machine, console)
+	fmt.Fprintf(console.out, "Debugging %s. Type \"help\" for the commands.\n", filePath)
+
+	runErr := machine.ExecuteScript(script)
+	switch {
+	case errors.Is(runErr, errDebugQuit):
+		os.Exit(1)
+	case runErr != nil:
+		fmt.Fprintf(os.Stderr, "Fatal error: %v\n", runErr)
+	default:
+		fmt.Fprintln(console.out, "\n[script finished]")
+	}
+	os.Exit(machine.ExitStatus())
+}
+
+// debugConsole is the line-based user interface of the debugger
+type debugConsole struct {
+	in      *bufio.Reader
+	out     io.Writer
+	script  string              // Script being debugged, for breakpoints given by line
+	sources map[string][]string // Lines of the files shown, by path
+	last    string              // Last command, repeated by an empty line
+}
+
+// Break shows where the script stopped, with the watch expressions, and
+// runs commands until one resumes it
+func (c *debugConsole) Break(d *vm.Debugger, stop *vm.DebugStop) (vm.DebugAction, error) {
+	if stop.Breakpoint != nil {
+		fmt.Fprintf(c.out, "\nBreakpoint %d, ", stop.Breakpoint.ID)
+	} else {
+		fmt.Fprintln(c.out)
+	}
+	fmt.Fprintf(c.out, "%s() at %s:%d\n", stop.Function, stop.File, stop.Line)
+	c.showLines(stop.File, stop.Line, 0)
+	for i, expr := range d.Watches() {
+		fmt.Fprintf(c.out, "%d: %s = %s\n", i+1, expr, c.evaluate(d, expr))
+	}
+
+	for {
+		fmt.Fprint(c.out, "(php-go) ")
+		line, err := c.in.ReadString('\n')
+		if err != nil && line == "" {
+			// At the end of the input the script runs to the end
+			return vm.DebugDetach, nil
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			line = c.last
+		}
+		c.last = line
+
+		command, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+		switch command {
+		case "":
+		case "continue", "c":
+			return vm.DebugContinue, nil
+		case "step", "s":
+			return vm.DebugStepInto, nil
+		case "next", "n":
+			return vm.DebugStepOver, nil
+		case "finish", "f":
+			return vm.DebugStepOut, nil
+		case "detach":
+			return vm.DebugDetach, nil
+		case "quit", "q":
+			return vm.DebugContinue, errDebugQuit
+		case "break", "b":
+			c.addBreakpoint(d, arg, stop)
+		case "delete", "d":
+			id, err := strconv.Atoi(arg)
+			if err != nil || !d.RemoveBreakpoint(id) {
+				fmt.Fprintf(c.out, "No breakpoint %s\n", arg)
+			}
+		case "info":
+			c.listBreakpoints(d)
+		case "backtrace", "bt":
+			for i, frame := range d.Backtrace() {
+				fmt.Fprintf(c.out, "#%d %s() at %s:%d\n", i, frame.Function, frame.File, frame.Line)
+			}
+		case "locals":
+			c.showVariables(d.Locals(frameDepth(arg)))
+		case "stack":
+			c.showVariables(d.Stack(frameDepth(arg)))
+		case "this":
+			this, err := d.This(frameDepth(arg))
+			switch {
+			case err != nil:
+				fmt.Fprintln(c.out, err)
+			case this == nil:
+				fmt.Fprintln(c.out, "No $this in this frame")
+			default:
+				fmt.Fprintln(c.out, formatDebugValue(types.NewObject(this)))
+			}
+		case "print", "p":
+			fmt.Fprintln(c.out, c.evaluate(d, arg))
+		case "watch", "w":
+			d.AddWatch(arg)
+		case "unwatch":
+			n, err := strconv.Atoi(arg)
+			if err != nil || !d.RemoveWatch(n-1) {
+				fmt.Fprintf(c.out, "No watch %s\n", arg)
+			}
+		case "list", "l":
+			c.showLines(stop.File, stop.Line, 5)
+		case "help", "h":
+			fmt.Fprint(c.out, debugHelp)
+		default:
+			fmt.Fprintf(c.out, "Unknown command %q. Type \"help\" for the commands.\n", command)
+		}
+	}
+}
+
+// addBreakpoint sets a breakpoint given as file:line, a line of the
+// file stopped in, or a function or Class::method name
+func (c *debugConsole) addBreakpoint(d *vm.Debugger, spec string, stop *vm.DebugStop) {
+	if spec == "" {
+		fmt.Fprintln(c.out, "Usage: break <file:line|line|function>")
+		return
+	}
+	bp := &vm.Breakpoint{Function: spec}
+	if line, err := strconv.Atoi(spec); err == nil {
+		bp = &vm.Breakpoint{File: stop.File, Line: line}
+	} else if i := strings.LastIndex(spec, ":"); i > 0 && spec[i-1] != ':' {
+		if line, err := strconv.Atoi(spec[i+1:]); err == nil {
+			bp = &vm.Breakpoint{File: spec[:i], Line: line}
+		}
+	}
+	d.AddBreakpoint(bp)
+	fmt.Fprintf(c.out, "Breakpoint %d at %s\n", bp.ID, describeBreakpoint(bp))
+}
+
+// listBreakpoints shows the breakpoints with their hit counts
+func (c *debugConsole) listBreakpoints(d *vm.Debugger) {
+	if len(d.Breakpoints()) == 0 {
+		fmt.Fprintln(c.out, "No breakpoints")
+	}
+	for _, bp := range d.Breakpoints() {
+		fmt.Fprintf(c.out, "%d  %s (hit %d times)\n", bp.ID, describeBreakpoint(bp), bp.Hits)
+	}
+}
+
+// describeBreakpoint names where a breakpoint stops
+func describeBreakpoint(bp *vm.Breakpoint) string {
+	if bp.Function != "" {
+		return bp.Function + "()"
+	}
+	return fmt.Sprintf("%s:%d", bp.File, bp.Line)
+}
+
+// showVariables lists variables with their values
+func (c *debugConsole) showVariables(vars []vm.DebugVariable, err error) {
+	if err != nil {
+		fmt.Fprintln(c.out, err)
+		return
+	}
+	if len(vars) == 0 {
+		fmt.Fprintln(c.out, "(none)")
+	}
+	for _, v := range vars {
+		name := v.Name
+		if !strings.HasPrefix(name, "T") || !isDigits(name[1:]) {
+			name = "$" + name
+		}
+		fmt.Fprintf(c.out, "%s = %s\n", name, formatDebugValue(v.Value))
+	}
+}
+
+// evaluate evaluates an expression in the innermost frame for display
+func (c *debugConsole) evaluate(d *vm.Debugger, expr string) string {
+	if expr == "" {
+		return "Usage: print <expression>"
+	}
+	value, err := d.Eval(expr, 0)
+	if err != nil {
+		return fmt.Sprintf("<error: %v>", err)
+	}
+	return formatDebugValue(value)
+}
+
+// showLines prints the source around a line, marking it; context 0 shows
+// the line alone
+func (c *debugConsole) showLines(path string, line, context int) {
+	lines, ok := c.sources[path]
+	if !ok {
+		if data, err := os.ReadFile(path); err == nil {
+			lines = strings.Split(string(data), "\n")
+		}
+		c.sources[path] = lines
+	}
+	for n := max(line-context, 1); n <= line+context && n <= len(lines); n++ {
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(c.out, "%s%5d  %s\n", marker, n, lines[n-1])
+	}
+}
+
+// formatDebugValue renders a value the way var_export() does
+func formatDebugValue(value *types.Value) string {
+	if value == nil {
+		return "NULL"
+	}
+	return varfuncs.VarExport(value, types.NewBool(true)).ToString()
+}
+
+// frameDepth parses the frame number of an inspection command, the
+// innermost frame by default
+func frameDepth(arg string) int {
+	depth, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0
+	}
+	return depth
+}
+
+// isDigits reports whether s is a non-empty run of decimal digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+const debugHelp = `Execution:
+  step, s                  Run to the next line, entering calls
+  next, n                  Run to the next line of this function
+  finish, f                Run until this function returns
+  continue, c              Run to the next breakpoint
+  detach                   Run to the end without stopping
+  quit, q                  Abort the script
+Breakpoints:
+  break, b <file:line>     Stop at a line of a file (or its last path components)
+  break, b <line>          Stop at a line of the current file
+  break, b <function>      Stop on entry to a function or Class::method
+  delete, d <n>            Remove breakpoint n
+  info                     List the breakpoints
+Inspection (frame 0 is the innermost):
+  backtrace, bt            Show the call stack
+  locals [frame]           Show the variables of a frame
+  this [frame]             Show $this of a frame
+  stack [frame]            Show the temporaries of a frame
+  print, p <expr>          Evaluate a PHP expression in the current frame
+  watch, w <expr>          Evaluate an expression at every stop
+  unwatch <n>              Remove watch n
+  list, l                  Show the source around the current line
+An empty line repeats the last command.
+`
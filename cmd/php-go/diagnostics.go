@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/diagnostic"
+	"github.com/krizos/php-go/pkg/parser"
+)
+
+// errorFormatFlag parses --error-format=text|json, reporting whether errors
+// are written as JSON
+func errorFormatFlag(arg string) (bool, bool) {
+	format, ok := strings.CutPrefix(arg, "--error-format=")
+	if !ok {
+		return false, false
+	}
+	switch format {
+	case "text":
+		return false, true
+	case "json":
+		return true, true
+	}
+	fmt.Fprintf(os.Stderr, "Error: unknown error format '%s' (expected text or json)\n", format)
+	os.Exit(1)
+	return false, false
+}
+
+// writeDiagnostics writes diagnostics to stderr as a JSON document
+func writeDiagnostics(diagnostics []diagnostic.Diagnostic) {
+	if err := diagnostic.WriteJSON(os.Stderr, diagnostics); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+}
+
+// exitOnParseErrors prints the syntax errors of a parse, each with its
+// source excerpt or as JSON, and exits if there were any
+func exitOnParseErrors(p *parser.Parser, jsonErrors bool) {
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) == 0 {
+		return
+	}
+	if jsonErrors {
+		writeDiagnostics(diagnostic.FromParser(p))
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Parser encountered %d error(s):\n", len(diagnostics))
+	for i, d := range diagnostics {
+		fmt.Fprintf(os.Stderr, "  %d. %s\n", i+1, strings.ReplaceAll(d.Render(), "\n", "\n     "))
+	}
+	os.Exit(1)
+}
+
+// exitOnCompileError prints an error of the compiler and exits
+func exitOnCompileError(filePath string, err error, jsonErrors bool) {
+	if jsonErrors {
+		writeDiagnostics([]diagnostic.Diagnostic{diagnostic.FromCompileError(filePath, err)})
+	} else {
+		fmt.Fprintf(os.Stderr, "Compile error: %v\n", err)
+	}
+	os.Exit(1)
+}
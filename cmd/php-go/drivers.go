@@ -0,0 +1,7 @@
+package main
+
+// The database/sql drivers behind PDO (see pkg/stdlib/pdo). php-go links
+// SQLite, which needs cgo; a program embedding the VM imports the drivers
+// of the databases it uses, such as github.com/go-sql-driver/mysql or
+// github.com/jackc/pgx/v5/stdlib.
+import _ "github.com/mattn/go-sqlite3"
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/krizos/php-go/pkg/compiler"
+	"github.com/krizos/php-go/pkg/composer"
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/sapi/fastcgi"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// shutdownTimeout bounds the wait for running requests on shutdown
+const shutdownTimeout = 30 * time.Second
+
+// handleFastCGI serves PHP scripts over FastCGI on an address, like
+// PHP-FPM: "php-go -b 127.0.0.1:9000" or a Unix socket path. SIGHUP
+// reloads the configuration, and the Composer autoloading with
+// --composer, and drops the compiled scripts; SIGINT, SIGTERM and SIGQUIT
+// stop the server after the running requests.
+func handleFastCGI(args []string) {
+	var address string
+	iniFile := defaultIniFile
+	noIniFile := false
+	var directives []string
+	level := compiler.OptimizeNone
+	maxChildren := fastcgi.DefaultMaxChildren
+	useComposer, composerDir := false, ""
+
+	// Parse flags
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if optimization, ok := optimizationFlag(arg); ok {
+			level = optimization
+		} else if (arg == "-c" || arg == "-d") && i+1 < len(args) {
+			i++
+			if arg == "-c" {
+				iniFile = args[i]
+			} else {
+				directives = append(directives, args[i])
+			}
+		} else if strings.HasPrefix(arg, "-d") && len(arg) > 2 {
+			directives = append(directives, arg[2:])
+		} else if arg == "-n" {
+			noIniFile = true
+		} else if arg == "--composer" || strings.HasPrefix(arg, "--composer=") {
+			useComposer, composerDir = true, strings.TrimPrefix(strings.TrimPrefix(arg, "--composer"), "=")
+		} else if strings.HasPrefix(arg, "--max-children=") {
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--max-children="))
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --max-children value '%s'\n", arg)
+				os.Exit(1)
+			}
+			maxChildren = n
+		} else if address == "" {
+			address = arg
+		}
+	}
+
+	if address == "" {
+		fmt.Fprintln(os.Stderr, "Error: no address specified")
+		os.Exit(1)
+	}
+
+	if useComposer && composerDir == "" {
+		composerDir = "."
+	}
+	settings := func() (func(*vm.VM), error) {
+		configure, err := requestSettings(iniFile, noIniFile, directives)
+		if err != nil || !useComposer {
+			return configure, err
+		}
+		autoload, err := composer.Load(composerDir)
+		if err != nil {
+			return nil, err
+		}
+		return func(machine *vm.VM) {
+			configure(machine)
+			autoload.Install(machine)
+		}, nil
+	}
+
+	configure, err := settings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	network := "tcp"
+	if strings.Contains(address, "/") {
+		network = "unix"
+		os.Remove(address)
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	server := fastcgi.NewServer(compiler.ScriptCompiler(level), configure, maxChildren)
+	go handleServerSignals(server, settings)
+
+	log.Printf("php-go FastCGI server listening on %s (%d workers)", address, maxChildren)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, fastcgi.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// Serve returns on shutdown; the requests finish before the process exits
+	select {}
+}
+
+// handleServerSignals reloads the server on SIGHUP and shuts it down on
+// SIGINT, SIGTERM or SIGQUIT, exiting once the running requests finish
+func handleServerSignals(server *fastcgi.Server, reload func() (func(*vm.VM), error)) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	for sig := range signals {
+		if sig == syscall.SIGHUP {
+			configure, err := reload()
+			if err != nil {
+				log.Printf("Reload failed, keeping the configuration: %v", err)
+				continue
+			}
+			server.Reload(configure)
+			log.Printf("Configuration reloaded")
+			continue
+		}
+
+		log.Printf("Shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		err := server.Shutdown(ctx)
+		cancel()
+		if err != nil {
+			log.Printf("Shutdown: %v", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+}
+
+// requestSettings reads the configuration file and the -d directives once
+// and returns the function applying them to the VM of each request
+func requestSettings(iniFile string, noIniFile bool, directives []string) (func(*vm.VM), error) {
+	var settings [][2]string
+	if !noIniFile {
+		data, err := os.ReadFile(iniFile)
+		switch {
+		case err == nil:
+			pairs, err := runtime.ParseIni(string(data), iniFile)
+			if err != nil {
+				return nil, err
+			}
+			settings = append(settings, pairs...)
+		case iniFile != defaultIniFile || !errors.Is(err, os.ErrNotExist):
+			return nil, err
+		}
+	}
+
+	// The settings are checked once on a configuration of their own
+	check := vm.New().Config()
+	for _, pair := range settings {
+		if _, err := check.Set(pair[0], pair[1], runtime.INI_SYSTEM); err != nil {
+			return nil, fmt.Errorf("%s: %s", iniFile, err)
+		}
+	}
+	for _, directive := range directives {
+		name, value, ok := strings.Cut(directive, "=")
+		if !ok {
+			value = "1"
+		}
+		name = strings.TrimSpace(name)
+		if _, err := check.Set(name, value, runtime.INI_SYSTEM); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: -d %s: %v\n", directive, err)
+			continue
+		}
+		settings = append(settings, [2]string{name, value})
+	}
+
+	return func(machine *vm.VM) {
+		config := machine.Config()
+		for _, pair := range settings {
+			config.Set(pair[0], pair[1], runtime.INI_SYSTEM)
+		}
+	}, nil
+}
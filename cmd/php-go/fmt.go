@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/format"
+)
+
+// handleFmt formats PHP files: "php-go fmt --write src/". Without --write
+// or --diff the formatted files are printed. Directories are walked for
+// their .php files, skipping hidden directories and vendor. The exit
+// status is 1 if a file could not be formatted.
+func handleFmt(args []string) {
+	opts := format.DefaultOptions()
+	write, diff := false, false
+	var paths []string
+
+	// Parse flags
+	for _, arg := range args {
+		name, value, _ := strings.Cut(arg, "=")
+		switch {
+		case arg == "--write":
+			write = true
+		case arg == "--diff":
+			diff = true
+		case name == "--indent":
+			if value == "tab" {
+				opts.Indent = "\t"
+			} else if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				opts.Indent = strings.Repeat(" ", n)
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: invalid --indent value '%s' (expected a number of spaces or tab)\n", value)
+				os.Exit(1)
+			}
+		case name == "--line-length":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --line-length value '%s'\n", value)
+				os.Exit(1)
+			}
+			opts.LineLength = n
+		case name == "--braces":
+			switch value {
+			case "psr12":
+				opts.Braces = format.BracesPSR12
+			case "same-line":
+				opts.Braces = format.BracesSameLine
+			case "next-line":
+				opts.Braces = format.BracesNextLine
+			default:
+				fmt.Fprintf(os.Stderr, "Error: invalid --braces value '%s' (expected psr12, same-line or next-line)\n", value)
+				os.Exit(1)
+			}
+		case strings.HasPrefix(arg, "-"):
+			fmt.Fprintf(os.Stderr, "Error: unknown fmt option '%s'\n", arg)
+			os.Exit(1)
+		default:
+			paths = append(paths, arg)
+		}
+	}
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: fmt command requires a file or directory argument")
+		fmt.Fprintln(os.Stderr, "Usage: php-go fmt [--write|--diff] [--indent=N|tab] [--line-length=N] [--braces=psr12|same-line|next-line] <paths>")
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, file := range phpFiles(paths) {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file '%s': %v\n", file, err)
+			failed = true
+			continue
+		}
+		formatted, err := format.Source(source, file, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error formatting '%s': %v\n", file, err)
+			failed = true
+			continue
+		}
+		switch {
+		case diff:
+			fmt.Print(unifiedDiff(file, string(source), string(formatted)))
+		case write:
+			if string(formatted) != string(source) {
+				if err := os.WriteFile(file, formatted, 0644); err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing file '%s': %v\n", file, err)
+					failed = true
+				}
+			}
+		default:
+			os.Stdout.Write(formatted)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// phpFiles expands directories to the .php files they contain
+func phpFiles(paths []string) []string {
+	var files []string
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil || !info.IsDir() {
+			files = append(files, root)
+			continue
+		}
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(path, ".php") {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+// diffContext is the number of unchanged lines around the changes of a
+// hunk
+const diffContext = 3
+
+// unifiedDiff returns the changes from a to b in the unified format, or ""
+// if there are none
+func unifiedDiff(name, a, b string) string {
+	if a == b {
+		return ""
+	}
+	x, y := splitLines(a), splitLines(b)
+	ops := diffLines(x, y)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", name, name)
+	for start := 0; start < len(ops); {
+		// A hunk spans changes separated by at most twice the context
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*diffContext {
+				break
+			}
+		}
+		from, to := max(start-diffContext, 0), min(end+diffContext, len(ops))
+
+		lineA, lineB, countA, countB := 1, 1, 0, 0
+		for _, op := range ops[:from] {
+			if op.kind != '+' {
+				lineA++
+			}
+			if op.kind != '-' {
+				lineB++
+			}
+		}
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				countA++
+			}
+			if op.kind != '-' {
+				countB++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", lineA, countA, lineB, countB)
+		for _, op := range ops[from:to] {
+			out.WriteString(string(op.kind) + op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = to
+	}
+	return out.String()
+}
+
+// splitLines splits a text after its newlines
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffOp is a line kept (' '), removed ('-') or added ('+')
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// maxDiffCells bounds the table of the longest common subsequence: files
+// differing in more lines are diffed as a whole
+const maxDiffCells = 1 << 22
+
+// diffLines returns the edit script from x to y, from the longest common
+// subsequence of their lines after their common prefix and suffix
+func diffLines(x, y []string) []diffOp {
+	var ops []diffOp
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		ops = append(ops, diffOp{' ', x[prefix]})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+	a, b := x[prefix:len(x)-suffix], y[prefix:len(y)-suffix]
+
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for _, l := range a {
+			ops = append(ops, diffOp{'-', l})
+		}
+		for _, l := range b {
+			ops = append(ops, diffOp{'+', l})
+		}
+	} else {
+		// lcs[i][j] is the length of the common subsequence of a[i:], b[j:]
+		lcs := make([][]int, len(a)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(a) || j < len(b) {
+			switch {
+			case i < len(a) && j < len(b) && a[i] == b[j]:
+				ops = append(ops, diffOp{' ', a[i]})
+				i++
+				j++
+			case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+				ops = append(ops, diffOp{'-', a[i]})
+				i++
+			default:
+				ops = append(ops, diffOp{'+', b[j]})
+				j++
+			}
+		}
+	}
+
+	for _, l := range x[len(x)-suffix:] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	return ops
+}
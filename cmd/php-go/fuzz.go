@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krizos/php-go/pkg/fuzz"
+)
+
+// defaultFuzzIterations is the number of programs php-go fuzz runs
+const defaultFuzzIterations = 1000
+
+// handleFuzz runs a differential fuzzing campaign: "php-go fuzz -n 500
+// --corpus tests/ -o divergences/". Programs that run differently under
+// the reference php, or make php-go panic or hang, are reported and
+// written to the output directory. The exit status is 1 if any diverged.
+func handleFuzz(args []string) {
+	opts := fuzz.Options{Seed: time.Now().UnixNano(), Iterations: defaultFuzzIterations}
+	var corpusDir, outputDir, phpPath string
+	noPHP := false
+
+	// Parse flags
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if optimization, ok := optimizationFlag(arg); ok {
+			opts.Level = optimization
+		} else if arg == "--no-php" {
+			noPHP = true
+		} else if (arg == "-n" || arg == "--seed" || arg == "--timeout" || arg == "--corpus" || arg == "--php" || arg == "-o") && i+1 < len(args) {
+			i++
+			value := args[i]
+			var err error
+			switch arg {
+			case "-n":
+				opts.Iterations, err = strconv.Atoi(value)
+			case "--seed":
+				opts.Seed, err = strconv.ParseInt(value, 10, 64)
+			case "--timeout":
+				opts.Timeout, err = time.ParseDuration(value)
+			case "--corpus":
+				corpusDir = value
+			case "--php":
+				phpPath = value
+			case "-o":
+				outputDir = value
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid %s value '%s'\n", arg, value)
+				os.Exit(1)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: unknown fuzz option '%s'\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch {
+	case noPHP:
+	case phpPath != "":
+		opts.Reference = &fuzz.Reference{Path: phpPath}
+	default:
+		opts.Reference, _ = fuzz.FindReference()
+	}
+	if opts.Reference != nil {
+		version, err := opts.Reference.Version(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Comparing with PHP %s (%s)\n", version, opts.Reference.Path)
+	} else {
+		fmt.Println("No php binary: checking php-go for panics and hangs only")
+	}
+
+	if corpusDir != "" {
+		corpus, err := readCorpus(corpusDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading corpus: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Corpus = corpus
+	}
+	if outputDir != "" {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("Seed %d, %d programs\n", opts.Seed, opts.Iterations)
+
+	var writeErr error
+	stats, err := fuzz.Run(ctx, opts, func(d *fuzz.Divergence) {
+		fmt.Printf("\n--- %s", d.Program)
+		fmt.Print(d)
+		if outputDir != "" && writeErr == nil {
+			writeErr = writeDivergence(outputDir, d)
+		}
+	})
+	if err == nil {
+		err = writeErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n%d program(s), %d divergence(s)\n", stats.Programs, stats.Divergences)
+	if stats.Divergences > 0 {
+		os.Exit(1)
+	}
+}
+
+// readCorpus reads the .php files of a directory, recursively
+func readCorpus(dir string) ([]string, error) {
+	var corpus []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".php") {
+			return err
+		}
+		source, err := os.ReadFile(path)
+		if err == nil {
+			corpus = append(corpus, string(source))
+		}
+		return err
+	})
+	if err == nil && len(corpus) == 0 {
+		err = fmt.Errorf("no .php files in %s", dir)
+	}
+	return corpus, err
+}
+
+// writeDivergence saves a diverging program and its report, named after
+// the program's content so a program is saved once
+func writeDivergence(dir string, d *fuzz.Divergence) error {
+	hash := fnv.New32a()
+	hash.Write([]byte(d.Program))
+	name := filepath.Join(dir, fmt.Sprintf("divergence-%08x", hash.Sum32()))
+	if err := os.WriteFile(name+".php", []byte(d.Program), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(name+".txt", []byte(d.String()), 0644)
+}
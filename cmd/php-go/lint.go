@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/diagnostic"
+	"github.com/krizos/php-go/pkg/lint"
+)
+
+// handleLint analyzes PHP files together: "php-go lint src/". Findings are
+// printed one per line, or as JSON; those of the --baseline file are not.
+// --generate-baseline writes the current findings to a baseline instead.
+// The exit status is 1 if anything is reported.
+func handleLint(args []string) {
+	var opts lint.Options
+	var paths []string
+	baseline, generate := "", ""
+	jsonOutput := false
+
+	// Parse flags
+	for _, arg := range args {
+		name, value, _ := strings.Cut(arg, "=")
+		if isJSON, ok := errorFormatFlag(arg); ok {
+			jsonOutput = isJSON
+			continue
+		}
+		switch {
+		case name == "--enable":
+			opts.Enable = append(opts.Enable, lintRules(value)...)
+		case name == "--disable":
+			opts.Disable = append(opts.Disable, lintRules(value)...)
+		case name == "--baseline":
+			baseline = value
+		case name == "--generate-baseline":
+			generate = value
+		case strings.HasPrefix(arg, "-"):
+			fmt.Fprintf(os.Stderr, "Error: unknown lint option '%s'\n", arg)
+			os.Exit(1)
+		default:
+			paths = append(paths, arg)
+		}
+	}
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: lint command requires a file or directory argument")
+		fmt.Fprintln(os.Stderr, "Usage: php-go lint [--enable=rules] [--disable=rules] [--baseline=file] [--generate-baseline=file] [--error-format=json] <paths>")
+		fmt.Fprintln(os.Stderr, "\nRules:")
+		for _, r := range lint.Rules {
+			fmt.Fprintf(os.Stderr, "  %-20s %s\n", r.Rule, r.Description)
+		}
+		os.Exit(1)
+	}
+
+	project := lint.NewProject()
+	for _, file := range phpFiles(paths) {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file '%s': %v\n", file, err)
+			os.Exit(1)
+		}
+		project.AddFile(file, source)
+	}
+	diagnostics := project.Lint(opts)
+
+	if generate != "" {
+		if err := writeBaseline(generate, lint.NewBaseline(diagnostics)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing baseline '%s': %v\n", generate, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Baseline of %d finding(s) written to %s\n", len(diagnostics), generate)
+		return
+	}
+	if baseline != "" {
+		f, err := os.Open(baseline)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading baseline '%s': %v\n", baseline, err)
+			os.Exit(1)
+		}
+		b, err := lint.ReadBaseline(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading baseline '%s': %v\n", baseline, err)
+			os.Exit(1)
+		}
+		diagnostics = b.Filter(diagnostics)
+	}
+
+	if jsonOutput {
+		if err := diagnostic.WriteJSON(os.Stdout, diagnostics); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		for _, d := range diagnostics {
+			fmt.Println(d)
+		}
+	}
+	if len(diagnostics) > 0 {
+		os.Exit(1)
+	}
+}
+
+// lintRules parses a comma-separated list of rule names
+func lintRules(value string) []lint.Rule {
+	var rules []lint.Rule
+	for _, name := range strings.Split(value, ",") {
+		if !lint.IsRule(name) {
+			fmt.Fprintf(os.Stderr, "Error: unknown lint rule '%s'\n", name)
+			os.Exit(1)
+		}
+		rules = append(rules, lint.Rule(name))
+	}
+	return rules
+}
+
+// writeBaseline writes a baseline file
+func writeBaseline(path string, b *lint.Baseline) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := b.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
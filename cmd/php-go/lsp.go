@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/krizos/php-go/pkg/lsp"
+)
+
+// handleLSP runs the language server on stdin and stdout until the editor
+// asks it to exit
+func handleLSP(args []string) {
+	if len(args) > 0 && args[0] != "--stdio" {
+		fmt.Fprintf(os.Stderr, "Error: unknown lsp option '%s'\n", args[0])
+		os.Exit(1)
+	}
+	if err := lsp.NewServer().Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -2,11 +2,23 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krizos/php-go/pkg/bundle"
+	"github.com/krizos/php-go/pkg/compiler"
+	"github.com/krizos/php-go/pkg/composer"
+	"github.com/krizos/php-go/pkg/dbgp"
+	"github.com/krizos/php-go/pkg/diagnostic"
 	"github.com/krizos/php-go/pkg/lexer"
 	"github.com/krizos/php-go/pkg/parser"
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/vm"
 )
 
 const version = "0.0.1-dev"
@@ -36,6 +48,61 @@ func main() {
 		}
 		handleParse(os.Args[2:])
 
+	case "dump-bytecode":
+		if len(os.Args) < 3 {
+			fmt.Fprintln(os.Stderr, "Error: dump-bytecode command requires a file argument")
+			fmt.Fprintln(os.Stderr, "Usage: php-go dump-bytecode [-O0|-O1|-O2] <file>")
+			os.Exit(1)
+		}
+		handleDumpBytecode(os.Args[2:])
+
+	case "run":
+		if len(os.Args) < 3 {
+			fmt.Fprintln(os.Stderr, "Error: run command requires a file argument")
+			fmt.Fprintln(os.Stderr, "Usage: php-go run [-O0|-O1|-O2] [-c file] [-n] [-d name=value] [--profile=file] [--profile-opcodes[=N]] [--coverage=file] [--trace=file] [--composer[=dir]] [--error-format=json] <file>")
+			os.Exit(1)
+		}
+		handleRun(os.Args[2:])
+
+	case "debug":
+		if len(os.Args) < 3 {
+			fmt.Fprintln(os.Stderr, "Error: debug command requires a file argument")
+			fmt.Fprintln(os.Stderr, "Usage: php-go debug [-O0|-O1|-O2] [-c file] [-n] [-d name=value] <file>")
+			os.Exit(1)
+		}
+		handleDebug(os.Args[2:])
+
+	case "bundle":
+		if len(os.Args) < 3 {
+			fmt.Fprintln(os.Stderr, "Error: bundle command requires a file argument")
+			fmt.Fprintln(os.Stderr, "Usage: php-go bundle [-O0|-O1|-O2] [--compile] [--include path] -o <bundle> <entry.php>")
+			os.Exit(1)
+		}
+		handleBundle(os.Args[2:])
+
+	case "fuzz":
+		handleFuzz(os.Args[2:])
+
+	case "bench":
+		handleBench(os.Args[2:])
+
+	case "lsp":
+		handleLSP(os.Args[2:])
+
+	case "fmt":
+		handleFmt(os.Args[2:])
+
+	case "lint":
+		handleLint(os.Args[2:])
+
+	case "-b":
+		if len(os.Args) < 3 {
+			fmt.Fprintln(os.Stderr, "Error: -b requires an address")
+			fmt.Fprintln(os.Stderr, "Usage: php-go -b <address> [-O0|-O1|-O2] [-c file] [-n] [-d name=value] [--max-children=N] [--composer[=dir]]")
+			os.Exit(1)
+		}
+		handleFastCGI(os.Args[2:])
+
 	case "--version", "-v":
 		fmt.Printf("PHP-Go v%s\n", version)
 		fmt.Println("PHP 8.4 Interpreter in Go with Automatic Parallelization")
@@ -96,13 +163,15 @@ func handleLex(args []string) {
 }
 
 func handleParse(args []string) {
-	jsonOutput := false
+	jsonOutput, jsonErrors := false, false
 	var filePath string
 
 	// Parse flags
 	for _, arg := range args {
 		if arg == "--json" {
 			jsonOutput = true
+		} else if format, ok := errorFormatFlag(arg); ok {
+			jsonErrors = format
 		} else if filePath == "" {
 			filePath = arg
 		}
@@ -126,14 +195,7 @@ func handleParse(args []string) {
 	program := p.ParseProgram()
 
 	// Check for errors
-	errors := p.Errors()
-	if len(errors) > 0 {
-		fmt.Fprintf(os.Stderr, "Parser encountered %d error(s):\n", len(errors))
-		for i, msg := range errors {
-			fmt.Fprintf(os.Stderr, "  %d. %s\n", i+1, msg)
-		}
-		os.Exit(1)
-	}
+	exitOnParseErrors(p, jsonErrors)
 
 	// Output
 	if jsonOutput {
@@ -143,6 +205,407 @@ func handleParse(args []string) {
 	}
 }
 
+func handleDumpBytecode(args []string) {
+	level := compiler.OptimizeNone
+	jsonErrors := false
+	var filePath string
+	for _, arg := range args {
+		if optimization, ok := optimizationFlag(arg); ok {
+			level = optimization
+		} else if format, ok := errorFormatFlag(arg); ok {
+			jsonErrors = format
+		} else if filePath == "" {
+			filePath = arg
+		}
+	}
+	if filePath == "" {
+		fmt.Fprintln(os.Stderr, "Error: no file specified")
+		os.Exit(1)
+	}
+
+	// Read file
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file '%s': %v\n", filePath, err)
+		os.Exit(1)
+	}
+
+	// Parse
+	l := lexer.New(string(content), filePath)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	exitOnParseErrors(p, jsonErrors)
+
+	// Compile
+	c := compiler.New()
+	scriptPath, err := filepath.Abs(filePath)
+	if err != nil {
+		scriptPath = filePath
+	}
+	c.SetFile(scriptPath)
+	c.SetOptimizationLevel(level)
+	if err := c.Compile(program); err != nil {
+		exitOnCompileError(filePath, err, jsonErrors)
+	}
+
+	fmt.Printf("Bytecode for: %s\n\n", filePath)
+	fmt.Print(compiler.Disassemble(c.Bytecode()))
+}
+
+// optimizationFlag parses the -O0, -O1 and -O2 flags selecting the
+// optimization level
+func optimizationFlag(arg string) (compiler.OptimizationLevel, bool) {
+	switch arg {
+	case "-O0":
+		return compiler.OptimizeNone, true
+	case "-O1":
+		return compiler.OptimizePeephole, true
+	case "-O2":
+		return compiler.OptimizeDataFlow, true
+	}
+	return compiler.OptimizeNone, false
+}
+
+// defaultProfileTopN is the number of opcodes listed by --profile-opcodes
+const defaultProfileTopN = 10
+
+// defaultIniFile is the configuration file loaded from the working
+// directory unless -c or -n is given
+const defaultIniFile = "php-go.ini"
+
+func handleRun(args []string) {
+	profileTopN := 0
+	profilePath := ""
+	coveragePath := ""
+	tracePath := ""
+	var traceOptions vm.TraceOptions
+	var filePath string
+	iniFile := defaultIniFile
+	noIniFile := false
+	var directives []string
+	level := compiler.OptimizeNone
+	levelSet := false
+	useComposer, composerDir := false, ""
+	jsonErrors := false
+
+	// Parse flags
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if optimization, ok := optimizationFlag(arg); ok {
+			level, levelSet = optimization, true
+		} else if format, ok := errorFormatFlag(arg); ok {
+			jsonErrors = format
+		} else if (arg == "-c" || arg == "-d") && i+1 < len(args) {
+			i++
+			if arg == "-c" {
+				iniFile = args[i]
+			} else {
+				directives = append(directives, args[i])
+			}
+		} else if strings.HasPrefix(arg, "-d") && len(arg) > 2 {
+			directives = append(directives, arg[2:])
+		} else if arg == "-n" {
+			noIniFile = true
+		} else if arg == "--composer" || strings.HasPrefix(arg, "--composer=") {
+			useComposer, composerDir = true, strings.TrimPrefix(strings.TrimPrefix(arg, "--composer"), "=")
+		} else if strings.HasPrefix(arg, "--profile=") && len(arg) > len("--profile=") {
+			profilePath = strings.TrimPrefix(arg, "--profile=")
+		} else if strings.HasPrefix(arg, "--coverage=") && len(arg) > len("--coverage=") {
+			coveragePath = strings.TrimPrefix(arg, "--coverage=")
+		} else if strings.HasPrefix(arg, "--trace=") && len(arg) > len("--trace=") {
+			tracePath = strings.TrimPrefix(arg, "--trace=")
+		} else if strings.HasPrefix(arg, "--trace-function=") {
+			traceOptions.Functions = append(traceOptions.Functions, strings.Split(strings.TrimPrefix(arg, "--trace-function="), ",")...)
+		} else if strings.HasPrefix(arg, "--trace-file=") {
+			traceOptions.Files = append(traceOptions.Files, strings.Split(strings.TrimPrefix(arg, "--trace-file="), ",")...)
+		} else if strings.HasPrefix(arg, "--trace-ring=") {
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--trace-ring="))
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --trace-ring value '%s'\n", arg)
+				os.Exit(1)
+			}
+			traceOptions.RingSize = n
+		} else if arg == "--profile-opcodes" {
+			profileTopN = defaultProfileTopN
+		} else if strings.HasPrefix(arg, "--profile-opcodes=") {
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--profile-opcodes="))
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --profile-opcodes value '%s'\n", arg)
+				os.Exit(1)
+			}
+			profileTopN = n
+		} else if filePath == "" {
+			filePath = arg
+		}
+	}
+
+	if filePath == "" {
+		fmt.Fprintln(os.Stderr, "Error: no file specified")
+		os.Exit(1)
+	}
+
+	// A bundle runs its entry script, with the bundled files served to
+	// include and require ahead of the disk
+	var archive *bundle.Bundle
+	if bundle.IsBundle(filePath) {
+		var err error
+		if archive, err = bundle.Open(filePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening bundle '%s': %v\n", filePath, err)
+			os.Exit(1)
+		}
+		if !levelSet {
+			level = archive.OptimizationLevel
+		}
+	}
+
+	// Composer mode installs the autoloading of the project the script is
+	// in, or of the project given
+	var autoload *composer.Autoload
+	if useComposer {
+		if composerDir == "" {
+			root, ok := composer.Find(filepath.Dir(filePath))
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Error: no Composer project contains '%s'\n", filePath)
+				os.Exit(1)
+			}
+			composerDir = root
+		}
+		var err error
+		if autoload, err = composer.Load(composerDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading Composer autoloading: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var script *vm.Script
+	if archive != nil {
+		var err error
+		if script, err = archive.Script(compiler.ScriptCompiler(level)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error in bundle '%s': %v\n", filePath, err)
+			os.Exit(1)
+		}
+	} else {
+		script = compileFile(filePath, level, jsonErrors)
+	}
+
+	// Execute
+	machine := vm.New()
+	machine.SetScriptCompiler(compiler.ScriptCompiler(level))
+	if archive != nil {
+		archive.Mount(machine)
+	}
+	if autoload != nil {
+		autoload.Install(machine)
+	}
+	configure(machine.Config(), iniFile, noIniFile, directives)
+	if profileTopN > 0 {
+		machine.EnableOpcodeProfiling()
+	}
+	if profilePath != "" {
+		machine.EnableCallProfiling()
+	}
+	if coveragePath != "" {
+		machine.StartCoverage(vm.CoverageUnused)
+	}
+	var traceFile *os.File
+	if tracePath != "" || traceOptions.RingSize > 0 {
+		traceOptions.JSON = strings.HasSuffix(tracePath, ".jsonl")
+		if tracePath != "" && traceOptions.RingSize == 0 {
+			var err error
+			if traceFile, err = os.Create(tracePath); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		machine.EnableTracing(traceFile, traceOptions)
+	}
+
+	// With JSON errors the errors are collected rather than displayed
+	var diagnostics []diagnostic.Diagnostic
+	if jsonErrors {
+		machine.SetDisplayErrors(false)
+		machine.SetErrorListener(func(level runtime.ErrorType, message, file string, line int) {
+			diagnostics = append(diagnostics, diagnostic.FromRuntimeError(level, message, file, line))
+		})
+	}
+	// XDEBUG_SESSION and the like start a session with the IDE, as Xdebug
+	// does; the script runs undebugged if the IDE does not answer
+	var session *dbgp.Session
+	if cfg, ok := dbgp.ConfigFromEnv(os.Getenv); ok {
+		var err error
+		if session, err = dbgp.Connect(cfg, machine, filePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not connect to the debugging client at %s: %v\n", cfg.Address(), err)
+		} else {
+			machine.SetOutputWriter(session.Output(os.Stdout))
+		}
+	}
+
+	runErr := machine.ExecuteScript(script)
+	if session != nil {
+		session.Close(runErr)
+		if errors.Is(runErr, dbgp.ErrStopped) {
+			runErr = nil
+		}
+	}
+	fmt.Print(machine.GetOutput())
+
+	// The profile is reported even if the script failed
+	if profiler := machine.OpcodeProfile(); profiler != nil {
+		fmt.Fprintln(os.Stderr)
+		profiler.Report(os.Stderr, profileTopN)
+	}
+	if profiler := machine.CallProfile(); profiler != nil {
+		writeProfile(profiler, profilePath, filePath)
+	}
+	if coveragePath != "" {
+		writeCoverage(machine, coveragePath)
+	}
+	if tracer := machine.Tracer(); tracer != nil {
+		finishTrace(tracer, traceFile, tracePath, runErr)
+	}
+
+	switch {
+	case jsonErrors:
+		if runErr != nil {
+			diagnostics = append(diagnostics, diagnostic.FromError(filePath, runErr))
+		}
+		writeDiagnostics(diagnostics)
+	case runErr != nil:
+		fmt.Fprintf(os.Stderr, "Fatal error: %v\n", runErr)
+	}
+	// The status passed to exit() or die()
+	os.Exit(machine.ExitStatus())
+}
+
+// writeProfile writes the function profile of a script run to path
+func writeProfile(profiler *vm.CallProfiler, path, script string) {
+	out, err := os.Create(path)
+	if err == nil {
+		if strings.HasSuffix(path, ".folded") {
+			err = profiler.WriteFolded(out)
+		} else {
+			err = profiler.WriteCallgrind(out, script)
+		}
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing the profile: %v\n", err)
+	}
+}
+
+// finishTrace completes the trace of a script run. In ring-buffer mode
+// the last instructions are written only if the script failed, to path or
+// to stderr without one.
+func finishTrace(tracer *vm.Tracer, out *os.File, path string, runErr error) {
+	err := tracer.Close()
+	if out != nil {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	} else if runErr != nil {
+		if path == "" {
+			fmt.Fprintln(os.Stderr, "Last instructions executed:")
+			err = tracer.Dump(os.Stderr)
+		} else if out, err = os.Create(path); err == nil {
+			err = tracer.Dump(out)
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing the trace: %v\n", err)
+	}
+}
+
+// writeCoverage writes the lines a script run executed to path, as a
+// clover XML report if path ends with .xml and an lcov tracefile otherwise
+func writeCoverage(machine *vm.VM, path string) {
+	// The script may have discarded the coverage with xdebug_stop_code_coverage()
+	coverage := machine.Coverage()
+	if coverage == nil {
+		coverage = machine.StartCoverage(vm.CoverageUnused)
+	}
+	out, err := os.Create(path)
+	if err == nil {
+		if strings.HasSuffix(path, ".xml") {
+			err = coverage.WriteClover(out, time.Now())
+		} else {
+			err = coverage.WriteLcov(out)
+		}
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing the coverage: %v\n", err)
+	}
+}
+
+// compileFile parses and compiles a script for run, exiting on errors
+func compileFile(filePath string, level compiler.OptimizationLevel, jsonErrors bool) *vm.Script {
+	// Read file
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file '%s': %v\n", filePath, err)
+		os.Exit(1)
+	}
+
+	// Parse
+	l := lexer.New(string(content), filePath)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	exitOnParseErrors(p, jsonErrors)
+
+	// Compile
+	c := compiler.New()
+	scriptPath, err := filepath.Abs(filePath)
+	if err != nil {
+		scriptPath = filePath
+	}
+	c.SetFile(scriptPath)
+	c.SetOptimizationLevel(level)
+	if err := c.Compile(program); err != nil {
+		exitOnCompileError(filePath, err, jsonErrors)
+	}
+	bytecode := c.Bytecode()
+	return &vm.Script{
+		Path:         filePath,
+		Instructions: bytecode.Instructions,
+		Constants:    bytecode.Constants,
+		Variables:    bytecode.Variables,
+		StrictTypes:  bytecode.StrictTypes,
+		TryCatch:     bytecode.TryCatch,
+	}
+}
+
+// configure loads the configuration file, which must exist if given with
+// -c, and applies the -d directives over it
+func configure(config *runtime.Config, iniFile string, noIniFile bool, directives []string) {
+	if !noIniFile {
+		_, statErr := os.Stat(iniFile)
+		if iniFile != defaultIniFile || statErr == nil {
+			if err := config.LoadFile(iniFile); err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+	for _, directive := range directives {
+		name, value, ok := strings.Cut(directive, "=")
+		if !ok {
+			value = "1"
+		}
+		if _, err := config.Set(strings.TrimSpace(name), value, runtime.INI_SYSTEM); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: -d %s: %v\n", directive, err)
+		}
+	}
+}
+
 func outputTokensHuman(tokens []lexer.Token, filePath string) {
 	fmt.Printf("Tokens for: %s\n", filePath)
 	fmt.Printf("Total: %d tokens\n\n", len(tokens))
@@ -211,19 +674,87 @@ func printUsage() {
 	fmt.Println("  php-go <file>              Execute PHP file (Phase 2+)")
 	fmt.Println("  php-go -a                  Interactive mode (Phase 2+)")
 	fmt.Println("  php-go -S host:port        Built-in web server (Phase 3+)")
+	fmt.Println("  php-go -b host:port|path   FastCGI server for nginx or Apache, like PHP-FPM")
 	fmt.Println("  php-go --version, -v       Show version")
 	fmt.Println("  php-go --help, -h          Show this help")
 	fmt.Println()
 	fmt.Println("Development commands:")
 	fmt.Println("  php-go lex [--json] <file>     Tokenize file and show tokens")
 	fmt.Println("  php-go parse [--json] <file>   Parse file and show AST")
+	fmt.Println("  php-go run [options] <file>    Compile and execute file")
+	fmt.Println("  php-go dump-bytecode <file>    Compile file and list its opcodes")
+	fmt.Println("  php-go debug [options] <file>  Execute file in the interactive debugger, stopping on its first line")
+	fmt.Println("  php-go fuzz [options]          Compare php-go with the php binary on random programs")
+	fmt.Println("  php-go bench [options] [paths] Time the standard benchmarks, or files and the .php files of")
+	fmt.Println("                                 directories, and compare them with a baseline")
+	fmt.Println("  php-go lsp [--stdio]           Run the language server for editors on stdin and stdout")
+	fmt.Println("  php-go fmt [options] <paths>   Format files, or the .php files of directories, in the PSR-12 style")
+	fmt.Println("  php-go lint [options] <paths>  Report likely mistakes in files, or the .php files of directories")
+	fmt.Println("  php-go bundle [options] -o <bundle> <file>")
+	fmt.Println("                                 Pack file and the files it includes into a bundle for run")
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  --json                     Output in JSON format")
+	fmt.Println("  -O0, -O1, -O2              Compile without optimization (default), with the peephole optimizer,")
+	fmt.Println("                             or with the peephole and data-flow optimizers")
+	fmt.Println("  --profile=file             Write the time, calls and memory of each function to file, in the")
+	fmt.Println("                             callgrind format, or as folded stacks for flame graphs if file ends")
+	fmt.Println("                             with .folded")
+	fmt.Println("  --profile-opcodes[=N]      Report the top N opcodes by count and time (default 10)")
+	fmt.Println("  --trace=file               Write each instruction executed with its operand and result values")
+	fmt.Println("                             to file, as JSON Lines if file ends with .jsonl")
+	fmt.Println("  --trace-function=name      Trace only the instructions of the functions or Class::methods given")
+	fmt.Println("  --trace-file=path          Trace only the instructions of the files given")
+	fmt.Println("  --trace-ring=N             Keep the last N instructions traced and write them, to the --trace")
+	fmt.Println("                             file or stderr, only if the script fails")
+	fmt.Println("  --coverage=file            Write the lines the script executed to file, as a clover XML report")
+	fmt.Println("                             if file ends with .xml or an lcov tracefile otherwise")
+	fmt.Println("  --error-format=json        Write parse, compile and runtime errors to stderr as JSON (parse,")
+	fmt.Println("                             dump-bytecode, run; default text), or the lint findings to stdout")
+	fmt.Println("  -c <file>                  Load the configuration from file (default ./php-go.ini if present)")
+	fmt.Println("  -n                         Load no configuration file")
+	fmt.Println("  -d name=value              Set an ini directive")
+	fmt.Println("  --max-children=N           Requests the FastCGI server runs at once (default 5)")
+	fmt.Println("  --composer[=dir]           Install the autoloading Composer generated for the project in dir")
+	fmt.Println("                             (default: the project containing the file, or the working directory")
+	fmt.Println("                             for -b) without running Composer's autoloader")
+	fmt.Println("  -o <file>                  Write the bundle to file (bundle), or the diverging programs to")
+	fmt.Println("                             the directory (fuzz)")
+	fmt.Println("  -n N, --seed S             Programs to run (default 1000) and their random seed (fuzz)")
+	fmt.Println("  --corpus <dir>             Mutate the .php files of dir as well as generating programs (fuzz)")
+	fmt.Println("  --php <path>, --no-php     Reference php binary (default $PHP_BINARY or php), or none (fuzz)")
+	fmt.Println("  --timeout <duration>       Time limit of each program (fuzz, default 5s)")
+	fmt.Println("  -n N, --warmup=N           Timed and untimed runs of each benchmark (bench, default 10 and 2)")
+	fmt.Println("  --filter=PATTERNS          Run only the benchmarks whose names contain one of the comma-separated")
+	fmt.Println("                             patterns (bench)")
+	fmt.Println("  --baseline=FILE            Compare with the results saved in FILE, failing on regressions (bench)")
+	fmt.Println("  --save=FILE                Save the results as JSON, a baseline for later runs (bench)")
+	fmt.Println("  --threshold=PCT            Slowdown counted as a regression (bench, default 10%)")
+	fmt.Println("  --list                     List the benchmarks without running them (bench)")
+	fmt.Println("  --include <path>           Bundle a file, or the files of a directory, that is not included statically")
+	fmt.Println("  --compile                  Compile the bundled files, refusing code that does not compile")
+	fmt.Println("  --write, --diff            Rewrite the files, or print the changes as a unified diff (fmt; default:")
+	fmt.Println("                             print the formatted files)")
+	fmt.Println("  --indent=N|tab             Indent with N spaces or a tab (fmt, default 4)")
+	fmt.Println("  --line-length=N            Break lines longer than N columns (fmt, default 120)")
+	fmt.Println("  --braces=STYLE             Opening braces: psr12, same-line or next-line (fmt, default psr12)")
+	fmt.Println("  --enable=RULES             Run only these comma-separated rules (lint; default all)")
+	fmt.Println("  --disable=RULES            Do not run these comma-separated rules (lint)")
+	fmt.Println("  --baseline=FILE            Report only the findings the baseline file does not record (lint)")
+	fmt.Println("  --generate-baseline=FILE   Record the current findings in a baseline file (lint)")
+	fmt.Println()
+	fmt.Println("Environment:")
+	fmt.Println("  XDEBUG_SESSION=<idekey>    Debug the script (run) with the IDE listening for DBGp connections")
+	fmt.Println("  XDEBUG_CONFIG=\"...\"        The IDE's client_host (default localhost), client_port (default")
+	fmt.Println("                             9003) and idekey, as with Xdebug")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  php-go lex test.php        Show tokens from test.php")
 	fmt.Println("  php-go parse test.php      Show AST from test.php")
 	fmt.Println("  php-go parse --json test.php   Show AST in JSON format")
+	fmt.Println("  php-go dump-bytecode test.php  Show the opcodes of test.php")
+	fmt.Println("  php-go -b 127.0.0.1:9000   Serve PHP to nginx on port 9000")
+	fmt.Println("  php-go bundle -o app.bundle index.php && php-go run app.bundle")
+	fmt.Println("  php-go bench --save=base.json && php-go bench --baseline=base.json")
 	fmt.Println()
 }
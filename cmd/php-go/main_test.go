@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the command instead of the tests when runCommand starts
+// the test binary as php-go
+func TestMain(m *testing.M) {
+	if args := os.Getenv("PHP_GO_TEST_ARGS"); args != "" {
+		os.Args = append([]string{"php-go"}, strings.Split(args, "\x1f")...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runCommand runs php-go with arguments, returning its output and exit
+// status
+func runCommand(t *testing.T, args ...string) (stdout, stderr string, status int) {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "PHP_GO_TEST_ARGS="+strings.Join(args, "\x1f"))
+	var out, errOut bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		status = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("Running php-go failed: %v", err)
+	}
+	return out.String(), errOut.String(), status
+}
+
+// writeScript writes a PHP script to a temporary file
+func writeScript(t *testing.T, source string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.php")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun_ProfileOpcodes(t *testing.T) {
+	script := writeScript(t, `<?php
+function twice($n) { return $n * 2; }
+$sum = 0;
+for ($i = 0; $i < 5; $i++) { $sum += twice($i); }
+echo "sum=$sum\n";
+`)
+
+	stdout, stderr, status := runCommand(t, "run", "-n", script)
+	if status != 0 || stdout != "sum=20\n" || stderr != "" {
+		t.Fatalf("Expected sum=20 and status 0, got %q (status %d, stderr %q)", stdout, status, stderr)
+	}
+
+	// The profile goes to stderr, leaving the output of the script alone
+	stdout, stderr, status = runCommand(t, "run", "-n", "--profile-opcodes=3", script)
+	if status != 0 || stdout != "sum=20\n" {
+		t.Fatalf("Expected sum=20 and status 0, got %q (status %d, stderr %q)", stdout, status, stderr)
+	}
+	for _, want := range []string{"Opcode profile:", "Top 3 opcodes by count:", "Per function:", "  twice (", "MUL"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("Profile does not contain %q:\n%s", want, stderr)
+		}
+	}
+}
+
+func TestRun_FatalError(t *testing.T) {
+	script := writeScript(t, "<?php\necho \"before\\n\";\nundefined_function();\necho \"after\\n\";\n")
+
+	stdout, stderr, status := runCommand(t, "run", "-n", script)
+	if status != 255 || !strings.HasPrefix(stdout, "before\n") || strings.Contains(stdout, "after") {
+		t.Errorf("Expected the script to stop with status 255, got %q (status %d, stderr %q)", stdout, status, stderr)
+	}
+	if !strings.Contains(stdout+stderr, "undefined_function()") {
+		t.Errorf("Expected the fatal error to name the function, got %q and %q", stdout, stderr)
+	}
+}
+
+func TestRun_PDOSQLite(t *testing.T) {
+	script := writeScript(t, `<?php
+$db = new PDO("sqlite::memory:");
+$db->setAttribute(PDO::ATTR_ERRMODE, PDO::ERRMODE_EXCEPTION);
+$db->exec("CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)");
+$insert = $db->prepare("INSERT INTO t (name) VALUES (?)");
+$insert->execute(["a"]);
+$insert->execute(["b"]);
+foreach ($db->query("SELECT id, name FROM t ORDER BY id")->fetchAll(PDO::FETCH_ASSOC) as $row) {
+    echo $row['id'], "=", $row['name'], " ";
+}
+foreach (PDO::getAvailableDrivers() as $driver) {
+    echo $driver, " ";
+}
+`)
+
+	stdout, stderr, status := runCommand(t, "run", "-n", script)
+	if status != 0 || !strings.HasPrefix(stdout, "1=a 2=b ") || !strings.Contains(stdout, "sqlite") {
+		t.Errorf("Expected the rows and the sqlite driver, got %q (status %d, stderr %q)", stdout, status, stderr)
+	}
+}
+
+func TestRun_ReferenceArguments(t *testing.T) {
+	script := writeScript(t, `<?php
+exec('printf "a\nb"', $out, $rc); echo $out[1], $rc, " ";
+system('exit 3', $code); echo $code, " ";
+$p = proc_open('cat', [0 => ['pipe', 'r'], 1 => ['pipe', 'w']], $pipes);
+fwrite($pipes[0], "piped"); fclose($pipes[0]); echo stream_get_contents($pipes[1]), " "; proc_close($p);
+$fp = @fsockopen("127.0.0.1", 1, $errno, $errstr, 1); echo ($errno > 0 && $errstr !== "") ? "refused" : "?", " ";
+$s = (new PDO("sqlite::memory:"))->prepare("SELECT ? AS v"); $s->bindParam(1, $val); $val = "late"; $s->execute(); echo $s->fetchColumn(), " ";
+$data = [3, 1, 2]; $names = ["c", "a", "b"]; array_multisort($data, $names); echo $names[0], $names[2];
+`)
+
+	stdout, stderr, status := runCommand(t, "run", "-n", script)
+	if want := "b0 3 piped refused late ac"; status != 0 || stdout != want {
+		t.Errorf("Expected %q, got %q (status %d, stderr %q)", want, stdout, status, stderr)
+	}
+}
@@ -1,3 +1,10 @@
 module github.com/krizos/php-go
 
 go 1.25.4
+
+require (
+	github.com/mattn/go-sqlite3 v1.14.33
+	golang.org/x/crypto v0.45.0
+)
+
+require golang.org/x/sys v0.38.0 // indirect
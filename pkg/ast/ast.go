@@ -1,13 +1,17 @@
 package ast
 
 import (
+	"strings"
+
 	"github.com/krizos/php-go/pkg/lexer"
 )
 
 // Node is the base interface for all AST nodes
 type Node interface {
-	TokenLiteral() string // Returns the literal value of the token
-	String() string       // Returns a string representation for debugging
+	TokenLiteral() string   // Returns the literal value of the token
+	String() string         // Returns a string representation for debugging
+	Pos() lexer.Position    // Position of the first token of the node
+	End() lexer.Position    // Position just past the last token of the node
 }
 
 // Stmt represents a statement node
@@ -24,6 +28,7 @@ type Expr interface {
 
 // Program is the root node of the AST
 type Program struct {
+	Span
 	Statements []Stmt
 }
 
@@ -44,6 +49,7 @@ func (p *Program) String() string {
 
 // Identifier represents an identifier (variable name, function name, etc.)
 type Identifier struct {
+	Span
 	Token lexer.Token // The IDENT token
 	Value string
 }
@@ -56,6 +62,7 @@ func (i *Identifier) String() string       { return i.Value }
 
 // ExpressionStatement wraps an expression as a statement
 type ExpressionStatement struct {
+	Span
 	Token      lexer.Token // The first token of the expression
 	Expression Expr
 }
@@ -71,6 +78,7 @@ func (es *ExpressionStatement) String() string {
 
 // BlockStatement represents a block of statements
 type BlockStatement struct {
+	Span
 	Token      lexer.Token // The { token
 	Statements []Stmt
 }
@@ -90,6 +98,7 @@ func (bs *BlockStatement) String() string {
 
 // IntegerLiteral represents an integer literal
 type IntegerLiteral struct {
+	Span
 	Token lexer.Token
 	Value int64
 }
@@ -100,6 +109,7 @@ func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
 // StringLiteral represents a string literal
 type StringLiteral struct {
+	Span
 	Token lexer.Token
 	Value string
 }
@@ -112,6 +122,7 @@ func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 // Example: "Hello $name" or "Value: {$obj->prop}"
 // This is converted to a series of concatenations at compile time
 type InterpolatedStringExpression struct {
+	Span
 	Token lexer.Token // The opening quote
 	Parts []Expr      // Mix of StringLiteral and other expressions
 }
@@ -122,6 +133,7 @@ func (ise *InterpolatedStringExpression) String() string       { return `"...(in
 
 // BooleanLiteral represents a boolean literal
 type BooleanLiteral struct {
+	Span
 	Token lexer.Token
 	Value bool
 }
@@ -132,6 +144,7 @@ func (bl *BooleanLiteral) String() string       { return bl.Token.Literal }
 
 // NullLiteral represents a null literal
 type NullLiteral struct {
+	Span
 	Token lexer.Token
 }
 
@@ -139,8 +152,20 @@ func (nl *NullLiteral) expressionNode()      {}
 func (nl *NullLiteral) TokenLiteral() string { return nl.Token.Literal }
 func (nl *NullLiteral) String() string       { return "null" }
 
+// MagicConstant represents __LINE__, __FILE__, __DIR__, __FUNCTION__,
+// __CLASS__, __TRAIT__, __METHOD__ or __NAMESPACE__
+type MagicConstant struct {
+	Span
+	Token lexer.Token
+}
+
+func (mc *MagicConstant) expressionNode()      {}
+func (mc *MagicConstant) TokenLiteral() string { return mc.Token.Literal }
+func (mc *MagicConstant) String() string       { return mc.Token.Type.String() }
+
 // Variable represents a PHP variable ($var)
 type Variable struct {
+	Span
 	Token lexer.Token
 	Name  string // Without the $ prefix
 }
@@ -149,8 +174,27 @@ func (v *Variable) expressionNode()      {}
 func (v *Variable) TokenLiteral() string { return v.Token.Literal }
 func (v *Variable) String() string       { return v.Token.Literal }
 
+// VariableVariable represents a variable whose name is the value of an
+// expression: $$name, ${'a' . 'b'}
+type VariableVariable struct {
+	Span
+	Token lexer.Token // The '$' token
+	Name  Expr        // Expression evaluating to the variable name
+}
+
+func (vv *VariableVariable) expressionNode()      {}
+func (vv *VariableVariable) TokenLiteral() string { return vv.Token.Literal }
+func (vv *VariableVariable) String() string {
+	switch vv.Name.(type) {
+	case *Variable, *VariableVariable:
+		return "$" + vv.Name.String()
+	}
+	return "${" + vv.Name.String() + "}"
+}
+
 // FloatLiteral represents a floating-point literal
 type FloatLiteral struct {
+	Span
 	Token lexer.Token
 	Value float64
 }
@@ -161,6 +205,7 @@ func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
 
 // PrefixExpression represents a prefix operator expression (!, -, +, ~, ++, --)
 type PrefixExpression struct {
+	Span
 	Token    lexer.Token // The prefix operator token
 	Operator string
 	Right    Expr
@@ -174,6 +219,7 @@ func (pe *PrefixExpression) String() string {
 
 // InfixExpression represents a binary operator expression
 type InfixExpression struct {
+	Span
 	Token    lexer.Token // The operator token
 	Left     Expr
 	Operator string
@@ -188,6 +234,7 @@ func (ie *InfixExpression) String() string {
 
 // AssignmentExpression represents an assignment operation
 type AssignmentExpression struct {
+	Span
 	Token    lexer.Token // The = or +=, -=, etc. token
 	Left     Expr        // Variable, property, or array access
 	Operator string      // =, +=, -=, *=, etc.
@@ -202,6 +249,7 @@ func (ae *AssignmentExpression) String() string {
 
 // TernaryExpression represents a ternary conditional (? :)
 type TernaryExpression struct {
+	Span
 	Token       lexer.Token // The ? token
 	Condition   Expr
 	Consequence Expr // Can be nil for short ternary (?:)
@@ -219,13 +267,15 @@ func (te *TernaryExpression) String() string {
 
 // ArrayExpression represents an array literal [key => value, ...]
 type ArrayExpression struct {
+	Span
 	Token    lexer.Token // The [ token
 	Elements []ArrayElement
 }
 
 type ArrayElement struct {
 	Key   Expr // nil for non-associative elements
-	Value Expr
+	Value Expr // nil for an element skipped in a list assignment
+	ByRef bool // true for &$value
 }
 
 func (ae *ArrayExpression) expressionNode()      {}
@@ -234,8 +284,36 @@ func (ae *ArrayExpression) String() string {
 	return "[array]"
 }
 
+// ListExpression represents a destructuring assignment target, list($a, $b)
+// or the short form [$a, $b], possibly nested and keyed: ['k' => [$x, $y]]
+type ListExpression struct {
+	Span
+	Token    lexer.Token // The LIST or [ token
+	Elements []ArrayElement
+}
+
+func (le *ListExpression) expressionNode()      {}
+func (le *ListExpression) TokenLiteral() string { return le.Token.Literal }
+func (le *ListExpression) String() string {
+	elements := make([]string, len(le.Elements))
+	for i, elem := range le.Elements {
+		if elem.Value == nil {
+			continue
+		}
+		if elem.Key != nil {
+			elements[i] = elem.Key.String() + " => "
+		}
+		if elem.ByRef {
+			elements[i] += "&"
+		}
+		elements[i] += elem.Value.String()
+	}
+	return "list(" + strings.Join(elements, ", ") + ")"
+}
+
 // IndexExpression represents array/string access $arr[$index]
 type IndexExpression struct {
+	Span
 	Token lexer.Token // The [ token
 	Left  Expr        // The array or string
 	Index Expr
@@ -249,6 +327,7 @@ func (ie *IndexExpression) String() string {
 
 // PropertyExpression represents property access $obj->prop
 type PropertyExpression struct {
+	Span
 	Token    lexer.Token // The -> token
 	Object   Expr
 	Property Expr // Can be Identifier or dynamic expression
@@ -262,6 +341,7 @@ func (pe *PropertyExpression) String() string {
 
 // NullsafePropertyExpression represents nullsafe property access $obj?->prop
 type NullsafePropertyExpression struct {
+	Span
 	Token    lexer.Token // The ?-> token
 	Object   Expr
 	Property Expr
@@ -275,6 +355,7 @@ func (npe *NullsafePropertyExpression) String() string {
 
 // StaticPropertyExpression represents static property access Class::$prop
 type StaticPropertyExpression struct {
+	Span
 	Token    lexer.Token // The :: token
 	Class    Expr        // Class name or expression
 	Property Expr
@@ -288,6 +369,7 @@ func (spe *StaticPropertyExpression) String() string {
 
 // CallExpression represents a function call func($args)
 type CallExpression struct {
+	Span
 	Token     lexer.Token // The ( token
 	Function  Expr        // Identifier, method call, or closure
 	Arguments []Expr
@@ -301,20 +383,27 @@ func (ce *CallExpression) String() string {
 
 // MethodCallExpression represents a method call $obj->method($args)
 type MethodCallExpression struct {
-	Token     lexer.Token // The -> token
+	Span
+	Token     lexer.Token // The -> or ?-> token
 	Object    Expr
 	Method    Expr // Can be Identifier or dynamic expression
 	Arguments []Expr
+	Nullsafe  bool // $obj?->method()
 }
 
 func (mce *MethodCallExpression) expressionNode()      {}
 func (mce *MethodCallExpression) TokenLiteral() string { return mce.Token.Literal }
 func (mce *MethodCallExpression) String() string {
-	return mce.Object.String() + "->" + mce.Method.String() + "(...)"
+	operator := "->"
+	if mce.Nullsafe {
+		operator = "?->"
+	}
+	return mce.Object.String() + operator + mce.Method.String() + "(...)"
 }
 
 // StaticCallExpression represents a static method call Class::method($args)
 type StaticCallExpression struct {
+	Span
 	Token     lexer.Token // The :: token
 	Class     Expr        // Class name or expression (self, parent, static)
 	Method    Expr
@@ -327,8 +416,54 @@ func (sce *StaticCallExpression) String() string {
 	return sce.Class.String() + "::" + sce.Method.String() + "(...)"
 }
 
+// VariadicPlaceholder represents the "..." argument of a first-class callable
+// Example: strlen(...), $obj->method(...), Foo::bar(...) (PHP 8.1+)
+type VariadicPlaceholder struct {
+	Span
+	Token lexer.Token // The ELLIPSIS token
+}
+
+func (vp *VariadicPlaceholder) expressionNode()      {}
+func (vp *VariadicPlaceholder) TokenLiteral() string { return vp.Token.Literal }
+func (vp *VariadicPlaceholder) String() string       { return "..." }
+
+// SpreadExpression represents argument unpacking in a call: f(...$args)
+type SpreadExpression struct {
+	Span
+	Token lexer.Token // The ELLIPSIS token
+	Value Expr        // Array or Traversable to unpack
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) String() string       { return "..." + se.Value.String() }
+
+// NamedArgument represents an argument passed by parameter name:
+// f(name: $value) (PHP 8.0+)
+type NamedArgument struct {
+	Span
+	Token lexer.Token // The parameter name token
+	Name  string
+	Value Expr
+}
+
+func (na *NamedArgument) expressionNode()      {}
+func (na *NamedArgument) TokenLiteral() string { return na.Token.Literal }
+func (na *NamedArgument) String() string       { return na.Name + ": " + na.Value.String() }
+
+// IsFirstClassCallable reports whether a call's argument list is the
+// first-class callable syntax f(...)
+func IsFirstClassCallable(args []Expr) bool {
+	if len(args) != 1 {
+		return false
+	}
+	_, ok := args[0].(*VariadicPlaceholder)
+	return ok
+}
+
 // NewExpression represents object instantiation new Class($args)
 type NewExpression struct {
+	Span
 	Token     lexer.Token // The NEW token
 	Class     Expr        // Class name or expression
 	Arguments []Expr
@@ -342,6 +477,7 @@ func (ne *NewExpression) String() string {
 
 // InstanceofExpression represents instanceof check
 type InstanceofExpression struct {
+	Span
 	Token lexer.Token // The INSTANCEOF token
 	Left  Expr
 	Right Expr // Class name or expression
@@ -355,6 +491,7 @@ func (ie *InstanceofExpression) String() string {
 
 // CastExpression represents type casting (int)$var
 type CastExpression struct {
+	Span
 	Token lexer.Token // The opening ( token
 	Type  string      // int, string, bool, etc.
 	Expr  Expr
@@ -366,8 +503,72 @@ func (ce *CastExpression) String() string {
 	return "((" + ce.Type + ")" + ce.Expr.String() + ")"
 }
 
+// IncludeExpression represents include, include_once, require and require_once
+// Example: require_once __DIR__ . '/config.php'
+type IncludeExpression struct {
+	Span
+	Token lexer.Token // The INCLUDE, INCLUDE_ONCE, REQUIRE or REQUIRE_ONCE token
+	Kind  string      // "include", "include_once", "require" or "require_once"
+	Path  Expr
+}
+
+func (ie *IncludeExpression) expressionNode()      {}
+func (ie *IncludeExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IncludeExpression) String() string {
+	return ie.Kind + " " + ie.Path.String()
+}
+
+// ExitExpression represents exit or die, with an optional status
+// Example: exit(1), die("error")
+type ExitExpression struct {
+	Span
+	Token  lexer.Token // The EXIT token (exit or die)
+	Status Expr        // Exit code or message (nil if omitted)
+}
+
+func (ee *ExitExpression) expressionNode()      {}
+func (ee *ExitExpression) TokenLiteral() string { return ee.Token.Literal }
+func (ee *ExitExpression) String() string {
+	if ee.Status == nil {
+		return ee.Token.Literal
+	}
+	return ee.Token.Literal + "(" + ee.Status.String() + ")"
+}
+
+// IssetExpression represents isset($a, $b['k'], ...), which is true when
+// every variable is set and not null
+type IssetExpression struct {
+	Span
+	Token     lexer.Token // The ISSET token
+	Variables []Expr
+}
+
+func (ie *IssetExpression) expressionNode()      {}
+func (ie *IssetExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IssetExpression) String() string {
+	vars := make([]string, len(ie.Variables))
+	for i, v := range ie.Variables {
+		vars[i] = v.String()
+	}
+	return "isset(" + strings.Join(vars, ", ") + ")"
+}
+
+// EmptyExpression represents empty(expr)
+type EmptyExpression struct {
+	Span
+	Token lexer.Token // The EMPTY token
+	Expr  Expr
+}
+
+func (ee *EmptyExpression) expressionNode()      {}
+func (ee *EmptyExpression) TokenLiteral() string { return ee.Token.Literal }
+func (ee *EmptyExpression) String() string {
+	return "empty(" + ee.Expr.String() + ")"
+}
+
 // GroupedExpression represents an expression in parentheses
 type GroupedExpression struct {
+	Span
 	Token lexer.Token // The ( token
 	Expr  Expr
 }
@@ -381,6 +582,7 @@ func (ge *GroupedExpression) String() string {
 // ClosureExpression represents an anonymous function (closure)
 // Example: function($x) use ($y) { return $x + $y; }
 type ClosureExpression struct {
+	Span
 	Token      lexer.Token   // The FUNCTION token
 	Parameters []*Parameter  // Function parameters
 	Use        []*UseClause  // Variables captured from parent scope
@@ -388,6 +590,7 @@ type ClosureExpression struct {
 	Body       *BlockStatement
 	ByRef      bool          // Returns reference (&function)
 	Static     bool          // static function() (cannot use $this)
+	Attributes []*AttributeGroup
 }
 
 func (ce *ClosureExpression) expressionNode()      {}
@@ -406,12 +609,14 @@ type UseClause struct {
 // ArrowFunctionExpression represents an arrow function (PHP 7.4+)
 // Example: fn($x) => $x * 2
 type ArrowFunctionExpression struct {
+	Span
 	Token      lexer.Token  // The FN token
 	Parameters []*Parameter // Function parameters
 	ReturnType Expr         // Return type hint (can be nil)
 	Body       Expr         // Single expression (not a block)
 	ByRef      bool         // Returns reference
 	Static     bool         // static fn()
+	Attributes []*AttributeGroup
 }
 
 func (af *ArrowFunctionExpression) expressionNode()      {}
@@ -422,6 +627,7 @@ func (af *ArrowFunctionExpression) String() string {
 
 // EchoStatement represents echo statement
 type EchoStatement struct {
+	Span
 	Token       lexer.Token // The ECHO token
 	Expressions []Expr
 }
@@ -432,8 +638,105 @@ func (es *EchoStatement) String() string {
 	return "echo ..."
 }
 
+// StaticVarStatement represents static variable declarations:
+// static $a = 1, $b;
+type StaticVarStatement struct {
+	Span
+	Token lexer.Token // The STATIC token
+	Vars  []*StaticVar
+}
+
+// StaticVar is a single static variable with an optional initializer
+type StaticVar struct {
+	Name  *Variable
+	Value Expr // Can be nil
+}
+
+func (ss *StaticVarStatement) statementNode()       {}
+func (ss *StaticVarStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *StaticVarStatement) String() string {
+	return "static ..."
+}
+
+// GlobalStatement represents global variable imports: global $a, $b;
+type GlobalStatement struct {
+	Span
+	Token lexer.Token // The GLOBAL token
+	Vars  []*Variable
+}
+
+func (gs *GlobalStatement) statementNode()       {}
+func (gs *GlobalStatement) TokenLiteral() string { return gs.Token.Literal }
+func (gs *GlobalStatement) String() string {
+	return "global ..."
+}
+
+// ConstStatement represents a constant declaration outside classes:
+// const A = 1, B = 2;
+type ConstStatement struct {
+	Span
+	Token     lexer.Token // The CONST token
+	Constants []*ConstantItem
+}
+
+func (cs *ConstStatement) statementNode()       {}
+func (cs *ConstStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ConstStatement) String() string {
+	items := make([]string, len(cs.Constants))
+	for i, item := range cs.Constants {
+		items[i] = item.Name.Value + " = " + item.Value.String()
+	}
+	return "const " + strings.Join(items, ", ") + ";"
+}
+
+// DeclareDirective is one directive of a declare statement: strict_types=1
+type DeclareDirective struct {
+	Name  string
+	Value Expr
+}
+
+// DeclareStatement represents declare(strict_types=1); and the block forms
+// declare(ticks=1) { ... } and declare(ticks=1): ... enddeclare;
+type DeclareStatement struct {
+	Span
+	Token      lexer.Token // The DECLARE token
+	Directives []*DeclareDirective
+	Body       *BlockStatement // nil for declare(...);
+}
+
+func (ds *DeclareStatement) statementNode()       {}
+func (ds *DeclareStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DeclareStatement) String() string {
+	directives := make([]string, len(ds.Directives))
+	for i, d := range ds.Directives {
+		directives[i] = d.Name + "=" + d.Value.String()
+	}
+	if ds.Body != nil {
+		return "declare(" + strings.Join(directives, ", ") + ") { ... }"
+	}
+	return "declare(" + strings.Join(directives, ", ") + ");"
+}
+
+// UnsetStatement represents unset($a, $b['k'], $c->p, ...)
+type UnsetStatement struct {
+	Span
+	Token     lexer.Token // The UNSET token
+	Variables []Expr
+}
+
+func (us *UnsetStatement) statementNode()       {}
+func (us *UnsetStatement) TokenLiteral() string { return us.Token.Literal }
+func (us *UnsetStatement) String() string {
+	vars := make([]string, len(us.Variables))
+	for i, v := range us.Variables {
+		vars[i] = v.String()
+	}
+	return "unset(" + strings.Join(vars, ", ") + ");"
+}
+
 // ReturnStatement represents return statement
 type ReturnStatement struct {
+	Span
 	Token       lexer.Token // The RETURN token
 	ReturnValue Expr        // Can be nil
 }
@@ -449,6 +752,7 @@ func (rs *ReturnStatement) String() string {
 
 // BreakStatement represents break statement
 type BreakStatement struct {
+	Span
 	Token lexer.Token // The BREAK token
 	Depth Expr        // Optional depth (break 2)
 }
@@ -461,6 +765,7 @@ func (bs *BreakStatement) String() string {
 
 // ContinueStatement represents continue statement
 type ContinueStatement struct {
+	Span
 	Token lexer.Token // The CONTINUE token
 	Depth Expr        // Optional depth (continue 2)
 }
@@ -473,6 +778,7 @@ func (cs *ContinueStatement) String() string {
 
 // IfStatement represents if/elseif/else statement
 type IfStatement struct {
+	Span
 	Token       lexer.Token // The IF token
 	Condition   Expr
 	Consequence *BlockStatement
@@ -494,6 +800,7 @@ func (is *IfStatement) String() string {
 
 // WhileStatement represents while loop
 type WhileStatement struct {
+	Span
 	Token     lexer.Token // The WHILE token
 	Condition Expr
 	Body      *BlockStatement
@@ -507,6 +814,7 @@ func (ws *WhileStatement) String() string {
 
 // DoWhileStatement represents do-while loop
 type DoWhileStatement struct {
+	Span
 	Token     lexer.Token // The DO token
 	Body      *BlockStatement
 	Condition Expr
@@ -520,6 +828,7 @@ func (dws *DoWhileStatement) String() string {
 
 // ForStatement represents for loop
 type ForStatement struct {
+	Span
 	Token      lexer.Token // The FOR token
 	Init       []Expr      // Initialization expressions
 	Condition  []Expr      // Condition expressions
@@ -535,6 +844,7 @@ func (fs *ForStatement) String() string {
 
 // ForeachStatement represents foreach loop
 type ForeachStatement struct {
+	Span
 	Token     lexer.Token // The FOREACH token
 	Array     Expr
 	Key       Expr        // Can be nil
@@ -551,6 +861,7 @@ func (fs *ForeachStatement) String() string {
 
 // SwitchStatement represents switch statement
 type SwitchStatement struct {
+	Span
 	Token   lexer.Token // The SWITCH token
 	Subject Expr
 	Cases   []*SwitchCase
@@ -570,6 +881,7 @@ func (ss *SwitchStatement) String() string {
 
 // MatchExpression represents match expression (PHP 8.0+)
 type MatchExpression struct {
+	Span
 	Token lexer.Token // The MATCH token
 	Subject Expr
 	Arms  []*MatchArm
@@ -589,6 +901,7 @@ func (me *MatchExpression) String() string {
 
 // TryStatement represents try-catch-finally statement
 type TryStatement struct {
+	Span
 	Token        lexer.Token // The TRY token
 	Body         *BlockStatement
 	CatchClauses []*CatchClause
@@ -610,6 +923,7 @@ func (ts *TryStatement) String() string {
 
 // ThrowStatement represents throw statement
 type ThrowStatement struct {
+	Span
 	Token      lexer.Token // The THROW token
 	Expression Expr
 }
@@ -622,6 +936,41 @@ func (ts *ThrowStatement) String() string {
 
 // Task 1.8: Declaration node types
 
+// AttributeGroup represents a group of attributes: #[A, B(1)]
+type AttributeGroup struct {
+	Span
+	Token      lexer.Token // The ATTRIBUTE_START token
+	Attributes []*Attribute
+}
+
+func (ag *AttributeGroup) TokenLiteral() string { return ag.Token.Literal }
+func (ag *AttributeGroup) String() string {
+	attrs := make([]string, len(ag.Attributes))
+	for i, attr := range ag.Attributes {
+		attrs[i] = attr.String()
+	}
+	return "#[" + strings.Join(attrs, ", ") + "]"
+}
+
+// Attribute represents an attribute: Name or Name(arguments)
+type Attribute struct {
+	Span
+	Name      *Identifier
+	Arguments []Expr // Positional and named arguments (nil without parentheses)
+}
+
+func (a *Attribute) TokenLiteral() string { return a.Name.Token.Literal }
+func (a *Attribute) String() string {
+	if a.Arguments == nil {
+		return a.Name.Value
+	}
+	args := make([]string, len(a.Arguments))
+	for i, arg := range a.Arguments {
+		args[i] = arg.String()
+	}
+	return a.Name.Value + "(" + strings.Join(args, ", ") + ")"
+}
+
 // Parameter represents a function/method parameter
 type Parameter struct {
 	Name         *Variable
@@ -629,16 +978,20 @@ type Parameter struct {
 	DefaultValue Expr // Default value (can be nil)
 	ByRef        bool // Pass by reference (&$param)
 	Variadic     bool // Variadic parameter (...$param)
+	Attributes   []*AttributeGroup
 }
 
 // FunctionDeclaration represents a function declaration
 type FunctionDeclaration struct {
+	Span
 	Token      lexer.Token // The FUNCTION token
 	Name       *Identifier
 	Parameters []*Parameter
 	ReturnType Expr // Return type hint (can be nil)
 	Body       *BlockStatement
 	ByRef      bool // Returns reference (&function)
+	Attributes []*AttributeGroup
+	DocComment string // /** */ comment preceding the declaration
 }
 
 func (fd *FunctionDeclaration) statementNode()       {}
@@ -649,12 +1002,15 @@ func (fd *FunctionDeclaration) String() string {
 
 // ClassDeclaration represents a class declaration
 type ClassDeclaration struct {
+	Span
 	Token      lexer.Token // The CLASS token
 	Name       *Identifier
 	Extends    *Identifier // Parent class (can be nil)
 	Implements []*Identifier
 	Body       []Stmt // Properties, methods, constants, trait uses
 	Modifiers  []string   // abstract, final
+	Attributes []*AttributeGroup
+	DocComment string // /** */ comment preceding the declaration
 }
 
 func (cd *ClassDeclaration) statementNode()       {}
@@ -665,12 +1021,15 @@ func (cd *ClassDeclaration) String() string {
 
 // PropertyDeclaration represents a class property
 type PropertyDeclaration struct {
+	Span
 	Token        lexer.Token // The first token (visibility or VAR)
 	Visibility   string      // public, protected, private
 	Static       bool
 	Readonly     bool
 	Type         Expr        // Type hint (can be nil)
 	Properties   []*PropertyItem
+	Attributes   []*AttributeGroup
+	DocComment   string // /** */ comment preceding the declaration
 }
 
 type PropertyItem struct {
@@ -686,6 +1045,7 @@ func (pd *PropertyDeclaration) String() string {
 
 // MethodDeclaration represents a class method
 type MethodDeclaration struct {
+	Span
 	Token      lexer.Token // The FUNCTION token
 	Visibility string      // public, protected, private
 	Static     bool
@@ -696,6 +1056,8 @@ type MethodDeclaration struct {
 	ReturnType Expr // Return type hint (can be nil)
 	Body       *BlockStatement // nil for abstract methods
 	ByRef      bool // Returns reference
+	Attributes []*AttributeGroup
+	DocComment string // /** */ comment preceding the declaration
 }
 
 func (md *MethodDeclaration) statementNode()       {}
@@ -706,10 +1068,14 @@ func (md *MethodDeclaration) String() string {
 
 // InterfaceDeclaration represents an interface declaration
 type InterfaceDeclaration struct {
+	Span
 	Token   lexer.Token // The INTERFACE token
 	Name    *Identifier
 	Extends []*Identifier // Interfaces can extend multiple interfaces
 	Body    []*MethodSignature
+	Constants []*ClassConstantDeclaration
+	Attributes []*AttributeGroup
+	DocComment string // /** */ comment preceding the declaration
 }
 
 type MethodSignature struct {
@@ -718,6 +1084,8 @@ type MethodSignature struct {
 	Parameters []*Parameter
 	ReturnType Expr // Return type hint (can be nil)
 	ByRef      bool
+	Attributes []*AttributeGroup
+	DocComment string // /** */ comment preceding the declaration
 }
 
 func (id *InterfaceDeclaration) statementNode()       {}
@@ -728,9 +1096,12 @@ func (id *InterfaceDeclaration) String() string {
 
 // TraitDeclaration represents a trait declaration
 type TraitDeclaration struct {
+	Span
 	Token lexer.Token // The TRAIT token
 	Name  *Identifier
 	Body  []Stmt // Properties and methods
+	Attributes []*AttributeGroup
+	DocComment string // /** */ comment preceding the declaration
 }
 
 func (td *TraitDeclaration) statementNode()       {}
@@ -739,11 +1110,107 @@ func (td *TraitDeclaration) String() string {
 	return "trait " + td.Name.Value + " { ... }"
 }
 
+// EnumDeclaration represents an enum declaration
+// Example: enum Suit: string implements HasColor { case Hearts = 'H'; ... }
+type EnumDeclaration struct {
+	Span
+	Token       lexer.Token // The ENUM token
+	Name        *Identifier
+	BackingType *Identifier // int or string for backed enums (can be nil)
+	Implements  []*Identifier
+	Body        []Stmt // Cases, constants, methods and trait uses
+	Attributes  []*AttributeGroup
+	DocComment  string // /** */ comment preceding the declaration
+}
+
+func (ed *EnumDeclaration) statementNode()       {}
+func (ed *EnumDeclaration) TokenLiteral() string { return ed.Token.Literal }
+func (ed *EnumDeclaration) String() string {
+	if ed.BackingType != nil {
+		return "enum " + ed.Name.Value + ": " + ed.BackingType.Value + " { ... }"
+	}
+	return "enum " + ed.Name.Value + " { ... }"
+}
+
+// EnumCaseDeclaration represents a case of an enum
+// Example: case Hearts = 'H';
+type EnumCaseDeclaration struct {
+	Span
+	Token lexer.Token // The CASE token
+	Name  *Identifier
+	Value Expr // Backing value (nil for pure enums)
+	Attributes []*AttributeGroup
+}
+
+func (ec *EnumCaseDeclaration) statementNode()       {}
+func (ec *EnumCaseDeclaration) TokenLiteral() string { return ec.Token.Literal }
+func (ec *EnumCaseDeclaration) String() string {
+	if ec.Value != nil {
+		return "case " + ec.Name.Value + " = " + ec.Value.String() + ";"
+	}
+	return "case " + ec.Name.Value + ";"
+}
+
+// NamespaceStatement represents a namespace declaration
+// Example: namespace App\Models; or namespace App { ... }
+type NamespaceStatement struct {
+	Span
+	Token lexer.Token     // The NAMESPACE token
+	Name  string          // Namespace name ("" for the global namespace block)
+	Body  *BlockStatement // Braced body (nil for the semicolon form)
+}
+
+func (ns *NamespaceStatement) statementNode()       {}
+func (ns *NamespaceStatement) TokenLiteral() string { return ns.Token.Literal }
+func (ns *NamespaceStatement) String() string {
+	if ns.Body != nil {
+		return "namespace " + ns.Name + " { ... }"
+	}
+	return "namespace " + ns.Name + ";"
+}
+
+// UseStatement represents a namespace import
+// Example: use App\Models\User as U, function App\helper, const App\VERSION;
+type UseStatement struct {
+	Span
+	Token lexer.Token // The USE token
+	Items []*UseItem
+}
+
+// UseItem is one imported name of a use statement
+type UseItem struct {
+	Kind  string // "" (class/namespace), "function" or "const"
+	Name  string // Fully qualified imported name (without leading backslash)
+	Alias string // Explicit alias ("" to use the last name segment)
+}
+
+func (us *UseStatement) statementNode()       {}
+func (us *UseStatement) TokenLiteral() string { return us.Token.Literal }
+func (us *UseStatement) String() string {
+	out := "use "
+	for i, item := range us.Items {
+		if i > 0 {
+			out += ", "
+		}
+		if item.Kind != "" {
+			out += item.Kind + " "
+		}
+		out += item.Name
+		if item.Alias != "" {
+			out += " as " + item.Alias
+		}
+	}
+	return out + ";"
+}
+
 // ClassConstantDeclaration represents class constants
 type ClassConstantDeclaration struct {
+	Span
 	Token      lexer.Token // The CONST token
 	Visibility string      // public, protected, private (PHP 7.1+)
 	Constants  []*ConstantItem
+	Attributes []*AttributeGroup
+	DocComment string // /** */ comment preceding the declaration
 }
 
 type ConstantItem struct {
@@ -759,6 +1226,7 @@ func (ccd *ClassConstantDeclaration) String() string {
 
 // TraitUse represents trait usage in a class
 type TraitUse struct {
+	Span
 	Token   lexer.Token // The USE token
 	Traits  []*Identifier
 	Adaptations []TraitAdaptation // insteadof, as
@@ -771,6 +1239,7 @@ type TraitAdaptation interface {
 
 // TraitPrecedence represents trait method precedence (insteadof)
 type TraitPrecedence struct {
+	Span
 	Token      lexer.Token // The INSTEADOF token
 	TraitName  *Identifier
 	MethodName *Identifier
@@ -779,10 +1248,17 @@ type TraitPrecedence struct {
 
 func (tp *TraitPrecedence) traitAdaptationNode()      {}
 func (tp *TraitPrecedence) TokenLiteral() string      { return tp.Token.Literal }
-func (tp *TraitPrecedence) String() string            { return "trait precedence" }
+func (tp *TraitPrecedence) String() string {
+	instead := make([]string, len(tp.Instead))
+	for i, name := range tp.Instead {
+		instead[i] = name.Value
+	}
+	return tp.TraitName.Value + "::" + tp.MethodName.Value + " insteadof " + strings.Join(instead, ", ") + ";"
+}
 
 // TraitAlias represents trait method aliasing
 type TraitAlias struct {
+	Span
 	Token      lexer.Token // The AS token
 	TraitName  *Identifier // Can be nil
 	MethodName *Identifier
@@ -792,7 +1268,20 @@ type TraitAlias struct {
 
 func (ta *TraitAlias) traitAdaptationNode()      {}
 func (ta *TraitAlias) TokenLiteral() string      { return ta.Token.Literal }
-func (ta *TraitAlias) String() string            { return "trait alias" }
+func (ta *TraitAlias) String() string {
+	out := ta.MethodName.Value
+	if ta.TraitName != nil {
+		out = ta.TraitName.Value + "::" + out
+	}
+	out += " as"
+	if ta.Visibility != "" {
+		out += " " + ta.Visibility
+	}
+	if ta.Alias != nil {
+		out += " " + ta.Alias.Value
+	}
+	return out + ";"
+}
 
 func (tu *TraitUse) statementNode()       {}
 func (tu *TraitUse) TokenLiteral() string { return tu.Token.Literal }
@@ -802,8 +1291,22 @@ func (tu *TraitUse) String() string {
 
 // Task 1.9: Type expression node types
 
+// TypeNode is a type declaration: an Identifier naming a type, or a
+// NullableType, UnionType or IntersectionType. A union whose members
+// include intersections is a DNF type: (A&B)|C
+type TypeNode interface {
+	Expr
+	typeNode()
+}
+
+func (i *Identifier) typeNode()        {}
+func (nt *NullableType) typeNode()     {}
+func (ut *UnionType) typeNode()        {}
+func (it *IntersectionType) typeNode() {}
+
 // NullableType represents a nullable type (?Type)
 type NullableType struct {
+	Span
 	Token lexer.Token // The ? token
 	Type  Expr
 }
@@ -816,6 +1319,7 @@ func (nt *NullableType) String() string {
 
 // UnionType represents a union type (Type1|Type2|Type3)
 type UnionType struct {
+	Span
 	Token lexer.Token // The first type token
 	Types []Expr
 }
@@ -828,13 +1332,18 @@ func (ut *UnionType) String() string {
 		if i > 0 {
 			s += "|"
 		}
-		s += t.String()
+		if _, ok := t.(*IntersectionType); ok {
+			s += "(" + t.String() + ")"
+		} else {
+			s += t.String()
+		}
 	}
 	return s
 }
 
 // IntersectionType represents an intersection type (Type1&Type2)
 type IntersectionType struct {
+	Span
 	Token lexer.Token // The first type token
 	Types []Expr
 }
@@ -0,0 +1,36 @@
+package ast
+
+import (
+	"reflect"
+
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+// Span is the extent of a node in the source: the position of its first
+// token and the position just past its last one. Every node type embeds
+// it, and the parser sets it on the nodes it produces.
+type Span struct {
+	StartPos lexer.Position
+	EndPos   lexer.Position
+}
+
+// Pos returns the position of the first token of the node
+func (s *Span) Pos() lexer.Position { return s.StartPos }
+
+// End returns the position just past the last token of the node
+func (s *Span) End() lexer.Position { return s.EndPos }
+
+// SetSpan sets the start and end positions of the node
+func (s *Span) SetSpan(start, end lexer.Position) {
+	s.StartPos, s.EndPos = start, end
+}
+
+// isNil reports whether a node is nil, including a nil pointer of a node
+// type stored in the interface
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
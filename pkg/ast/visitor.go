@@ -27,6 +27,20 @@ type Visitor interface {
 	VisitMethodDeclaration(node *MethodDeclaration) bool
 	VisitClassConstantDeclaration(node *ClassConstantDeclaration) bool
 	VisitTraitUse(node *TraitUse) bool
+	VisitProgram(node *Program) bool
+	VisitStaticVarStatement(node *StaticVarStatement) bool
+	VisitGlobalStatement(node *GlobalStatement) bool
+	VisitConstStatement(node *ConstStatement) bool
+	VisitDeclareStatement(node *DeclareStatement) bool
+	VisitUnsetStatement(node *UnsetStatement) bool
+	VisitNamespaceStatement(node *NamespaceStatement) bool
+	VisitUseStatement(node *UseStatement) bool
+	VisitEnumDeclaration(node *EnumDeclaration) bool
+	VisitEnumCaseDeclaration(node *EnumCaseDeclaration) bool
+	VisitTraitPrecedence(node *TraitPrecedence) bool
+	VisitTraitAlias(node *TraitAlias) bool
+	VisitAttributeGroup(node *AttributeGroup) bool
+	VisitAttribute(node *Attribute) bool
 
 	// Expression visitors
 	VisitIdentifier(node *Identifier) bool
@@ -56,319 +70,502 @@ type Visitor interface {
 	VisitNullableType(node *NullableType) bool
 	VisitUnionType(node *UnionType) bool
 	VisitIntersectionType(node *IntersectionType) bool
+	VisitInterpolatedStringExpression(node *InterpolatedStringExpression) bool
+	VisitMagicConstant(node *MagicConstant) bool
+	VisitVariableVariable(node *VariableVariable) bool
+	VisitListExpression(node *ListExpression) bool
+	VisitVariadicPlaceholder(node *VariadicPlaceholder) bool
+	VisitSpreadExpression(node *SpreadExpression) bool
+	VisitNamedArgument(node *NamedArgument) bool
+	VisitIncludeExpression(node *IncludeExpression) bool
+	VisitExitExpression(node *ExitExpression) bool
+	VisitIssetExpression(node *IssetExpression) bool
+	VisitEmptyExpression(node *EmptyExpression) bool
+	VisitClosureExpression(node *ClosureExpression) bool
+	VisitArrowFunctionExpression(node *ArrowFunctionExpression) bool
 }
 
-// Walk traverses the AST starting from the given node using the visitor pattern
+// Walk traverses the AST depth-first in source order, starting from the
+// given node: it calls the Visit method of the node's type, then walks the
+// node's children if that returns true
 func Walk(v Visitor, node Node) {
-	if node == nil {
+	if isNil(node) || !visit(v, node) {
 		return
 	}
+	walkChildren(node, func(child Node) {
+		Walk(v, child)
+	})
+}
+
+// Inspect traverses the AST depth-first in source order, starting from the
+// given node: it calls f for the node, then inspects the node's children
+// followed by a call of f(nil) if that returns true
+func Inspect(node Node, f func(Node) bool) {
+	if isNil(node) || !f(node) {
+		return
+	}
+	walkChildren(node, func(child Node) {
+		Inspect(child, f)
+	})
+	f(nil)
+}
 
+// visit calls the Visit method of a node's type
+func visit(v Visitor, node Node) bool {
 	switch n := node.(type) {
 	// Statements
+	case *Program:
+		return v.VisitProgram(n)
 	case *ExpressionStatement:
-		if v.VisitExpressionStatement(n) {
-			Walk(v, n.Expression)
-		}
+		return v.VisitExpressionStatement(n)
 	case *BlockStatement:
-		if v.VisitBlockStatement(n) {
-			for _, stmt := range n.Statements {
-				Walk(v, stmt)
-			}
-		}
+		return v.VisitBlockStatement(n)
 	case *EchoStatement:
-		if v.VisitEchoStatement(n) {
-			for _, expr := range n.Expressions {
-				Walk(v, expr)
-			}
-		}
+		return v.VisitEchoStatement(n)
 	case *ReturnStatement:
-		if v.VisitReturnStatement(n) {
-			Walk(v, n.ReturnValue)
-		}
+		return v.VisitReturnStatement(n)
 	case *BreakStatement:
-		if v.VisitBreakStatement(n) {
-			Walk(v, n.Depth)
-		}
+		return v.VisitBreakStatement(n)
 	case *ContinueStatement:
-		if v.VisitContinueStatement(n) {
-			Walk(v, n.Depth)
-		}
+		return v.VisitContinueStatement(n)
 	case *IfStatement:
-		if v.VisitIfStatement(n) {
-			Walk(v, n.Condition)
-			Walk(v, n.Consequence)
-			for _, elseif := range n.ElseIfs {
-				Walk(v, elseif.Condition)
-				Walk(v, elseif.Consequence)
-			}
-			Walk(v, n.Alternative)
-		}
+		return v.VisitIfStatement(n)
 	case *WhileStatement:
-		if v.VisitWhileStatement(n) {
-			Walk(v, n.Condition)
-			Walk(v, n.Body)
-		}
+		return v.VisitWhileStatement(n)
 	case *DoWhileStatement:
-		if v.VisitDoWhileStatement(n) {
-			Walk(v, n.Body)
-			Walk(v, n.Condition)
-		}
+		return v.VisitDoWhileStatement(n)
 	case *ForStatement:
-		if v.VisitForStatement(n) {
-			for _, expr := range n.Init {
-				Walk(v, expr)
-			}
-			for _, expr := range n.Condition {
-				Walk(v, expr)
-			}
-			for _, expr := range n.Increment {
-				Walk(v, expr)
-			}
-			Walk(v, n.Body)
-		}
+		return v.VisitForStatement(n)
 	case *ForeachStatement:
-		if v.VisitForeachStatement(n) {
-			Walk(v, n.Array)
-			Walk(v, n.Key)
-			Walk(v, n.Value)
-			Walk(v, n.Body)
-		}
+		return v.VisitForeachStatement(n)
 	case *SwitchStatement:
-		if v.VisitSwitchStatement(n) {
-			Walk(v, n.Subject)
-			for _, c := range n.Cases {
-				Walk(v, c.Value)
-				for _, stmt := range c.Body {
-					Walk(v, stmt)
-				}
-			}
-		}
+		return v.VisitSwitchStatement(n)
 	case *TryStatement:
-		if v.VisitTryStatement(n) {
-			Walk(v, n.Body)
-			for _, c := range n.CatchClauses {
-				for _, t := range c.Types {
-					Walk(v, t)
-				}
-				Walk(v, c.Variable)
-				Walk(v, c.Body)
-			}
-			Walk(v, n.Finally)
-		}
+		return v.VisitTryStatement(n)
 	case *ThrowStatement:
-		if v.VisitThrowStatement(n) {
-			Walk(v, n.Expression)
-		}
+		return v.VisitThrowStatement(n)
+	case *StaticVarStatement:
+		return v.VisitStaticVarStatement(n)
+	case *GlobalStatement:
+		return v.VisitGlobalStatement(n)
+	case *ConstStatement:
+		return v.VisitConstStatement(n)
+	case *DeclareStatement:
+		return v.VisitDeclareStatement(n)
+	case *UnsetStatement:
+		return v.VisitUnsetStatement(n)
+	case *NamespaceStatement:
+		return v.VisitNamespaceStatement(n)
+	case *UseStatement:
+		return v.VisitUseStatement(n)
 	case *FunctionDeclaration:
-		if v.VisitFunctionDeclaration(n) {
-			Walk(v, n.Name)
-			for _, p := range n.Parameters {
-				Walk(v, p.Type)
-				Walk(v, p.Name)
-				Walk(v, p.DefaultValue)
-			}
-			Walk(v, n.ReturnType)
-			Walk(v, n.Body)
-		}
+		return v.VisitFunctionDeclaration(n)
 	case *ClassDeclaration:
-		if v.VisitClassDeclaration(n) {
-			Walk(v, n.Name)
-			Walk(v, n.Extends)
-			for _, i := range n.Implements {
-				Walk(v, i)
-			}
-			for _, stmt := range n.Body {
-				Walk(v, stmt)
-			}
-		}
+		return v.VisitClassDeclaration(n)
 	case *InterfaceDeclaration:
-		if v.VisitInterfaceDeclaration(n) {
-			Walk(v, n.Name)
-			for _, e := range n.Extends {
-				Walk(v, e)
-			}
-			for _, m := range n.Body {
-				Walk(v, m.Name)
-				for _, p := range m.Parameters {
-					Walk(v, p.Type)
-					Walk(v, p.Name)
-					Walk(v, p.DefaultValue)
-				}
-				Walk(v, m.ReturnType)
-			}
-		}
+		return v.VisitInterfaceDeclaration(n)
 	case *TraitDeclaration:
-		if v.VisitTraitDeclaration(n) {
-			Walk(v, n.Name)
-			for _, stmt := range n.Body {
-				Walk(v, stmt)
-			}
-		}
+		return v.VisitTraitDeclaration(n)
+	case *EnumDeclaration:
+		return v.VisitEnumDeclaration(n)
+	case *EnumCaseDeclaration:
+		return v.VisitEnumCaseDeclaration(n)
 	case *PropertyDeclaration:
-		if v.VisitPropertyDeclaration(n) {
-			Walk(v, n.Type)
-			for _, p := range n.Properties {
-				Walk(v, p.Name)
-				Walk(v, p.DefaultValue)
-			}
-		}
+		return v.VisitPropertyDeclaration(n)
 	case *MethodDeclaration:
-		if v.VisitMethodDeclaration(n) {
-			Walk(v, n.Name)
-			for _, p := range n.Parameters {
-				Walk(v, p.Type)
-				Walk(v, p.Name)
-				Walk(v, p.DefaultValue)
-			}
-			Walk(v, n.ReturnType)
-			Walk(v, n.Body)
-		}
+		return v.VisitMethodDeclaration(n)
 	case *ClassConstantDeclaration:
-		if v.VisitClassConstantDeclaration(n) {
-			for _, c := range n.Constants {
-				Walk(v, c.Name)
-				Walk(v, c.Value)
-			}
-		}
+		return v.VisitClassConstantDeclaration(n)
 	case *TraitUse:
-		if v.VisitTraitUse(n) {
-			for _, t := range n.Traits {
-				Walk(v, t)
-			}
-		}
+		return v.VisitTraitUse(n)
+	case *TraitPrecedence:
+		return v.VisitTraitPrecedence(n)
+	case *TraitAlias:
+		return v.VisitTraitAlias(n)
+	case *AttributeGroup:
+		return v.VisitAttributeGroup(n)
+	case *Attribute:
+		return v.VisitAttribute(n)
 
 	// Expressions
 	case *Identifier:
-		v.VisitIdentifier(n)
+		return v.VisitIdentifier(n)
 	case *IntegerLiteral:
-		v.VisitIntegerLiteral(n)
+		return v.VisitIntegerLiteral(n)
 	case *FloatLiteral:
-		v.VisitFloatLiteral(n)
+		return v.VisitFloatLiteral(n)
 	case *StringLiteral:
-		v.VisitStringLiteral(n)
+		return v.VisitStringLiteral(n)
+	case *InterpolatedStringExpression:
+		return v.VisitInterpolatedStringExpression(n)
 	case *BooleanLiteral:
-		v.VisitBooleanLiteral(n)
+		return v.VisitBooleanLiteral(n)
 	case *NullLiteral:
-		v.VisitNullLiteral(n)
+		return v.VisitNullLiteral(n)
+	case *MagicConstant:
+		return v.VisitMagicConstant(n)
 	case *Variable:
-		v.VisitVariable(n)
+		return v.VisitVariable(n)
+	case *VariableVariable:
+		return v.VisitVariableVariable(n)
 	case *ArrayExpression:
-		if v.VisitArrayExpression(n) {
-			for _, elem := range n.Elements {
-				Walk(v, elem.Key)
-				Walk(v, elem.Value)
-			}
-		}
+		return v.VisitArrayExpression(n)
+	case *ListExpression:
+		return v.VisitListExpression(n)
 	case *PrefixExpression:
-		if v.VisitPrefixExpression(n) {
-			Walk(v, n.Right)
-		}
+		return v.VisitPrefixExpression(n)
 	case *InfixExpression:
-		if v.VisitInfixExpression(n) {
-			Walk(v, n.Left)
-			Walk(v, n.Right)
-		}
+		return v.VisitInfixExpression(n)
 	case *AssignmentExpression:
-		if v.VisitAssignmentExpression(n) {
-			Walk(v, n.Left)
-			Walk(v, n.Right)
-		}
+		return v.VisitAssignmentExpression(n)
 	case *TernaryExpression:
-		if v.VisitTernaryExpression(n) {
-			Walk(v, n.Condition)
-			Walk(v, n.Consequence)
-			Walk(v, n.Alternative)
-		}
+		return v.VisitTernaryExpression(n)
 	case *IndexExpression:
-		if v.VisitIndexExpression(n) {
-			Walk(v, n.Left)
-			Walk(v, n.Index)
-		}
+		return v.VisitIndexExpression(n)
 	case *PropertyExpression:
-		if v.VisitPropertyExpression(n) {
-			Walk(v, n.Object)
-			Walk(v, n.Property)
-		}
+		return v.VisitPropertyExpression(n)
 	case *NullsafePropertyExpression:
-		if v.VisitNullsafePropertyExpression(n) {
-			Walk(v, n.Object)
-			Walk(v, n.Property)
-		}
+		return v.VisitNullsafePropertyExpression(n)
 	case *StaticPropertyExpression:
-		if v.VisitStaticPropertyExpression(n) {
-			Walk(v, n.Class)
-			Walk(v, n.Property)
-		}
+		return v.VisitStaticPropertyExpression(n)
 	case *CallExpression:
-		if v.VisitCallExpression(n) {
-			Walk(v, n.Function)
-			for _, arg := range n.Arguments {
-				Walk(v, arg)
-			}
-		}
+		return v.VisitCallExpression(n)
 	case *MethodCallExpression:
-		if v.VisitMethodCallExpression(n) {
-			Walk(v, n.Object)
-			Walk(v, n.Method)
-			for _, arg := range n.Arguments {
-				Walk(v, arg)
-			}
-		}
+		return v.VisitMethodCallExpression(n)
 	case *StaticCallExpression:
-		if v.VisitStaticCallExpression(n) {
-			Walk(v, n.Class)
-			Walk(v, n.Method)
-			for _, arg := range n.Arguments {
-				Walk(v, arg)
-			}
-		}
+		return v.VisitStaticCallExpression(n)
+	case *VariadicPlaceholder:
+		return v.VisitVariadicPlaceholder(n)
+	case *SpreadExpression:
+		return v.VisitSpreadExpression(n)
+	case *NamedArgument:
+		return v.VisitNamedArgument(n)
 	case *NewExpression:
-		if v.VisitNewExpression(n) {
-			Walk(v, n.Class)
-			for _, arg := range n.Arguments {
-				Walk(v, arg)
-			}
-		}
+		return v.VisitNewExpression(n)
 	case *InstanceofExpression:
-		if v.VisitInstanceofExpression(n) {
-			Walk(v, n.Left)
-			Walk(v, n.Right)
-		}
+		return v.VisitInstanceofExpression(n)
 	case *CastExpression:
-		if v.VisitCastExpression(n) {
-			Walk(v, n.Expr)
-		}
+		return v.VisitCastExpression(n)
+	case *IncludeExpression:
+		return v.VisitIncludeExpression(n)
+	case *ExitExpression:
+		return v.VisitExitExpression(n)
+	case *IssetExpression:
+		return v.VisitIssetExpression(n)
+	case *EmptyExpression:
+		return v.VisitEmptyExpression(n)
 	case *GroupedExpression:
-		if v.VisitGroupedExpression(n) {
-			Walk(v, n.Expr)
+		return v.VisitGroupedExpression(n)
+	case *ClosureExpression:
+		return v.VisitClosureExpression(n)
+	case *ArrowFunctionExpression:
+		return v.VisitArrowFunctionExpression(n)
+	case *MatchExpression:
+		return v.VisitMatchExpression(n)
+	case *NullableType:
+		return v.VisitNullableType(n)
+	case *UnionType:
+		return v.VisitUnionType(n)
+	case *IntersectionType:
+		return v.VisitIntersectionType(n)
+	}
+	return true
+}
+
+// walkChildren calls f for each non-nil child of a node, in source order.
+// The nodes of the parts that are not nodes themselves (parameters, array
+// elements, catch clauses...) are children of the node containing them.
+func walkChildren(node Node, f func(Node)) {
+	// Fields of a concrete node type hold nil pointers, not nil interfaces
+	walk := func(child Node) {
+		if !isNil(child) {
+			f(child)
+		}
+	}
+	stmts := func(list []Stmt) {
+		for _, stmt := range list {
+			walk(stmt)
+		}
+	}
+	exprs := func(list []Expr) {
+		for _, expr := range list {
+			walk(expr)
+		}
+	}
+	identifiers := func(list []*Identifier) {
+		for _, identifier := range list {
+			walk(identifier)
+		}
+	}
+	attributes := func(list []*AttributeGroup) {
+		for _, group := range list {
+			walk(group)
 		}
+	}
+	elements := func(list []ArrayElement) {
+		for _, element := range list {
+			walk(element.Key)
+			walk(element.Value)
+		}
+	}
+	params := func(list []*Parameter) {
+		for _, param := range list {
+			attributes(param.Attributes)
+			walk(param.Type)
+			walk(param.Name)
+			walk(param.DefaultValue)
+		}
+	}
+	constants := func(list []*ConstantItem) {
+		for _, constant := range list {
+			walk(constant.Name)
+			walk(constant.Value)
+		}
+	}
+
+	switch n := node.(type) {
+	// Statements
+	case *Program:
+		stmts(n.Statements)
+	case *ExpressionStatement:
+		walk(n.Expression)
+	case *BlockStatement:
+		stmts(n.Statements)
+	case *EchoStatement:
+		exprs(n.Expressions)
+	case *ReturnStatement:
+		walk(n.ReturnValue)
+	case *BreakStatement:
+		walk(n.Depth)
+	case *ContinueStatement:
+		walk(n.Depth)
+	case *IfStatement:
+		walk(n.Condition)
+		walk(n.Consequence)
+		for _, elseif := range n.ElseIfs {
+			walk(elseif.Condition)
+			walk(elseif.Consequence)
+		}
+		walk(n.Alternative)
+	case *WhileStatement:
+		walk(n.Condition)
+		walk(n.Body)
+	case *DoWhileStatement:
+		walk(n.Body)
+		walk(n.Condition)
+	case *ForStatement:
+		exprs(n.Init)
+		exprs(n.Condition)
+		exprs(n.Increment)
+		walk(n.Body)
+	case *ForeachStatement:
+		walk(n.Array)
+		walk(n.Key)
+		walk(n.Value)
+		walk(n.Body)
+	case *SwitchStatement:
+		walk(n.Subject)
+		for _, c := range n.Cases {
+			walk(c.Value)
+			stmts(c.Body)
+		}
+	case *TryStatement:
+		walk(n.Body)
+		for _, c := range n.CatchClauses {
+			exprs(c.Types)
+			walk(c.Variable)
+			walk(c.Body)
+		}
+		walk(n.Finally)
+	case *ThrowStatement:
+		walk(n.Expression)
+	case *StaticVarStatement:
+		for _, v := range n.Vars {
+			walk(v.Name)
+			walk(v.Value)
+		}
+	case *GlobalStatement:
+		for _, v := range n.Vars {
+			walk(v)
+		}
+	case *ConstStatement:
+		constants(n.Constants)
+	case *DeclareStatement:
+		for _, d := range n.Directives {
+			walk(d.Value)
+		}
+		walk(n.Body)
+	case *UnsetStatement:
+		exprs(n.Variables)
+	case *NamespaceStatement:
+		walk(n.Body)
+	case *FunctionDeclaration:
+		attributes(n.Attributes)
+		walk(n.Name)
+		params(n.Parameters)
+		walk(n.ReturnType)
+		walk(n.Body)
+	case *ClassDeclaration:
+		attributes(n.Attributes)
+		walk(n.Name)
+		walk(n.Extends)
+		identifiers(n.Implements)
+		stmts(n.Body)
+	case *InterfaceDeclaration:
+		attributes(n.Attributes)
+		walk(n.Name)
+		identifiers(n.Extends)
+		for _, c := range n.Constants {
+			walk(c)
+		}
+		for _, m := range n.Body {
+			attributes(m.Attributes)
+			walk(m.Name)
+			params(m.Parameters)
+			walk(m.ReturnType)
+		}
+	case *TraitDeclaration:
+		attributes(n.Attributes)
+		walk(n.Name)
+		stmts(n.Body)
+	case *EnumDeclaration:
+		attributes(n.Attributes)
+		walk(n.Name)
+		walk(n.BackingType)
+		identifiers(n.Implements)
+		stmts(n.Body)
+	case *EnumCaseDeclaration:
+		attributes(n.Attributes)
+		walk(n.Name)
+		walk(n.Value)
+	case *PropertyDeclaration:
+		attributes(n.Attributes)
+		walk(n.Type)
+		for _, p := range n.Properties {
+			walk(p.Name)
+			walk(p.DefaultValue)
+		}
+	case *MethodDeclaration:
+		attributes(n.Attributes)
+		walk(n.Name)
+		params(n.Parameters)
+		walk(n.ReturnType)
+		walk(n.Body)
+	case *ClassConstantDeclaration:
+		attributes(n.Attributes)
+		constants(n.Constants)
+	case *TraitUse:
+		identifiers(n.Traits)
+		for _, adaptation := range n.Adaptations {
+			walk(adaptation)
+		}
+	case *TraitPrecedence:
+		walk(n.TraitName)
+		walk(n.MethodName)
+		identifiers(n.Instead)
+	case *TraitAlias:
+		walk(n.TraitName)
+		walk(n.MethodName)
+		walk(n.Alias)
+	case *AttributeGroup:
+		for _, attribute := range n.Attributes {
+			walk(attribute)
+		}
+	case *Attribute:
+		walk(n.Name)
+		exprs(n.Arguments)
+
+	// Expressions
+	case *InterpolatedStringExpression:
+		exprs(n.Parts)
+	case *VariableVariable:
+		walk(n.Name)
+	case *ArrayExpression:
+		elements(n.Elements)
+	case *ListExpression:
+		elements(n.Elements)
+	case *PrefixExpression:
+		walk(n.Right)
+	case *InfixExpression:
+		walk(n.Left)
+		walk(n.Right)
+	case *AssignmentExpression:
+		walk(n.Left)
+		walk(n.Right)
+	case *TernaryExpression:
+		walk(n.Condition)
+		walk(n.Consequence)
+		walk(n.Alternative)
+	case *IndexExpression:
+		walk(n.Left)
+		walk(n.Index)
+	case *PropertyExpression:
+		walk(n.Object)
+		walk(n.Property)
+	case *NullsafePropertyExpression:
+		walk(n.Object)
+		walk(n.Property)
+	case *StaticPropertyExpression:
+		walk(n.Class)
+		walk(n.Property)
+	case *CallExpression:
+		walk(n.Function)
+		exprs(n.Arguments)
+	case *MethodCallExpression:
+		walk(n.Object)
+		walk(n.Method)
+		exprs(n.Arguments)
+	case *StaticCallExpression:
+		walk(n.Class)
+		walk(n.Method)
+		exprs(n.Arguments)
+	case *SpreadExpression:
+		walk(n.Value)
+	case *NamedArgument:
+		walk(n.Value)
+	case *NewExpression:
+		walk(n.Class)
+		exprs(n.Arguments)
+	case *InstanceofExpression:
+		walk(n.Left)
+		walk(n.Right)
+	case *CastExpression:
+		walk(n.Expr)
+	case *IncludeExpression:
+		walk(n.Path)
+	case *ExitExpression:
+		walk(n.Status)
+	case *IssetExpression:
+		exprs(n.Variables)
+	case *EmptyExpression:
+		walk(n.Expr)
+	case *GroupedExpression:
+		walk(n.Expr)
+	case *ClosureExpression:
+		attributes(n.Attributes)
+		params(n.Parameters)
+		for _, use := range n.Use {
+			walk(use.Variable)
+		}
+		walk(n.ReturnType)
+		walk(n.Body)
+	case *ArrowFunctionExpression:
+		attributes(n.Attributes)
+		params(n.Parameters)
+		walk(n.ReturnType)
+		walk(n.Body)
 	case *MatchExpression:
-		if v.VisitMatchExpression(n) {
-			Walk(v, n.Subject)
-			for _, arm := range n.Arms {
-				for _, cond := range arm.Conditions {
-					Walk(v, cond)
-				}
-				Walk(v, arm.Body)
-			}
+		walk(n.Subject)
+		for _, arm := range n.Arms {
+			exprs(arm.Conditions)
+			walk(arm.Body)
 		}
 	case *NullableType:
-		if v.VisitNullableType(n) {
-			Walk(v, n.Type)
-		}
+		walk(n.Type)
 	case *UnionType:
-		if v.VisitUnionType(n) {
-			for _, t := range n.Types {
-				Walk(v, t)
-			}
-		}
+		exprs(n.Types)
 	case *IntersectionType:
-		if v.VisitIntersectionType(n) {
-			for _, t := range n.Types {
-				Walk(v, t)
-			}
-		}
+		exprs(n.Types)
 	}
 }
 
@@ -431,3 +628,30 @@ func (bv *BaseVisitor) VisitMatchExpression(node *MatchExpression) bool
 func (bv *BaseVisitor) VisitNullableType(node *NullableType) bool                 { return true }
 func (bv *BaseVisitor) VisitUnionType(node *UnionType) bool                       { return true }
 func (bv *BaseVisitor) VisitIntersectionType(node *IntersectionType) bool         { return true }
+func (bv *BaseVisitor) VisitProgram(node *Program) bool { return true }
+func (bv *BaseVisitor) VisitStaticVarStatement(node *StaticVarStatement) bool { return true }
+func (bv *BaseVisitor) VisitGlobalStatement(node *GlobalStatement) bool { return true }
+func (bv *BaseVisitor) VisitConstStatement(node *ConstStatement) bool { return true }
+func (bv *BaseVisitor) VisitDeclareStatement(node *DeclareStatement) bool { return true }
+func (bv *BaseVisitor) VisitUnsetStatement(node *UnsetStatement) bool { return true }
+func (bv *BaseVisitor) VisitNamespaceStatement(node *NamespaceStatement) bool { return true }
+func (bv *BaseVisitor) VisitUseStatement(node *UseStatement) bool { return true }
+func (bv *BaseVisitor) VisitEnumDeclaration(node *EnumDeclaration) bool { return true }
+func (bv *BaseVisitor) VisitEnumCaseDeclaration(node *EnumCaseDeclaration) bool { return true }
+func (bv *BaseVisitor) VisitTraitPrecedence(node *TraitPrecedence) bool { return true }
+func (bv *BaseVisitor) VisitTraitAlias(node *TraitAlias) bool { return true }
+func (bv *BaseVisitor) VisitAttributeGroup(node *AttributeGroup) bool { return true }
+func (bv *BaseVisitor) VisitAttribute(node *Attribute) bool { return true }
+func (bv *BaseVisitor) VisitInterpolatedStringExpression(node *InterpolatedStringExpression) bool { return true }
+func (bv *BaseVisitor) VisitMagicConstant(node *MagicConstant) bool { return true }
+func (bv *BaseVisitor) VisitVariableVariable(node *VariableVariable) bool { return true }
+func (bv *BaseVisitor) VisitListExpression(node *ListExpression) bool { return true }
+func (bv *BaseVisitor) VisitVariadicPlaceholder(node *VariadicPlaceholder) bool { return true }
+func (bv *BaseVisitor) VisitSpreadExpression(node *SpreadExpression) bool { return true }
+func (bv *BaseVisitor) VisitNamedArgument(node *NamedArgument) bool { return true }
+func (bv *BaseVisitor) VisitIncludeExpression(node *IncludeExpression) bool { return true }
+func (bv *BaseVisitor) VisitExitExpression(node *ExitExpression) bool { return true }
+func (bv *BaseVisitor) VisitIssetExpression(node *IssetExpression) bool { return true }
+func (bv *BaseVisitor) VisitEmptyExpression(node *EmptyExpression) bool { return true }
+func (bv *BaseVisitor) VisitClosureExpression(node *ClosureExpression) bool { return true }
+func (bv *BaseVisitor) VisitArrowFunctionExpression(node *ArrowFunctionExpression) bool { return true }
@@ -1,6 +1,8 @@
 package ast
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/krizos/php-go/pkg/lexer"
@@ -235,3 +237,67 @@ func (sv *SkippingVisitor) VisitVariable(node *Variable) bool {
 	sv.VisitedVar++
 	return true
 }
+
+// TestInspect tests the order of Inspect, including the nodes that are not
+// statements or expressions, and the f(nil) call closing each node
+func TestInspect(t *testing.T) {
+	closure := &ClosureExpression{
+		Parameters: []*Parameter{{
+			Type: &Identifier{Value: "int"},
+			Name: &Variable{Name: "a"},
+		}},
+		Use:  []*UseClause{{Variable: &Variable{Name: "b"}}},
+		Body: &BlockStatement{Statements: []Stmt{&ReturnStatement{ReturnValue: &Variable{Name: "a"}}}},
+	}
+	program := &Program{Statements: []Stmt{&ExpressionStatement{Expression: closure}}}
+
+	var order []string
+	depth := 0
+	Inspect(program, func(node Node) bool {
+		if node == nil {
+			depth--
+			return true
+		}
+		depth++
+		switch n := node.(type) {
+		case *Variable:
+			order = append(order, "$"+n.Name)
+		case *Identifier:
+			order = append(order, n.Value)
+		default:
+			order = append(order, fmt.Sprintf("%T", node)[5:])
+		}
+		return true
+	})
+
+	expected := "Program ExpressionStatement ClosureExpression int $a $b BlockStatement ReturnStatement $a"
+	if got := strings.Join(order, " "); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+	if depth != 0 {
+		t.Errorf("expected every node to be closed, depth is %d", depth)
+	}
+}
+
+// TestWalkAllNodes tests that Walk reaches the nodes of constructs the
+// original visitor skipped
+func TestWalkAllNodes(t *testing.T) {
+	program := &Program{
+		Statements: []Stmt{
+			&NamespaceStatement{Name: "App", Body: &BlockStatement{Statements: []Stmt{
+				&EnumDeclaration{
+					Name: &Identifier{Value: "Suit"},
+					Body: []Stmt{&EnumCaseDeclaration{Name: &Identifier{Value: "Hearts"}, Value: &StringLiteral{Value: "H"}}},
+				},
+				&GlobalStatement{Vars: []*Variable{{Name: "config"}}},
+				&EchoStatement{Expressions: []Expr{&ArrowFunctionExpression{Body: &Variable{Name: "x"}}}},
+			}}},
+		},
+	}
+
+	collector := &VariableCollector{Variables: []string{}}
+	Walk(collector, program)
+	if got := strings.Join(collector.Variables, ","); got != "config,x" {
+		t.Errorf("expected config,x, got %s", got)
+	}
+}
@@ -0,0 +1,115 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+)
+
+// ============================================================================
+// Baselines
+// ============================================================================
+
+// DefaultThreshold is the slowdown of a benchmark over its baseline that
+// counts as a regression
+const DefaultThreshold = 0.10
+
+// Report is the results of a benchmark run, the baseline later runs
+// compare with
+type Report struct {
+	Date       time.Time `json:"date"`
+	GoVersion  string    `json:"go_version"`
+	Platform   string    `json:"platform"`
+	Iterations int       `json:"iterations"`
+	Warmup     int       `json:"warmup"`
+	Results    []Result  `json:"results"`
+}
+
+// NewReport records the results of a run with the platform it ran on
+func NewReport(opts Options, results []Result) *Report {
+	return &Report{
+		Date:       time.Now().UTC().Truncate(time.Second),
+		GoVersion:  runtime.Version(),
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+		Iterations: opts.Iterations,
+		Warmup:     opts.Warmup,
+		Results:    results,
+	}
+}
+
+// Write writes a report as indented JSON
+func (r *Report) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Save writes a report to a file
+func (r *Report) Save(path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = r.Write(out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ReadReport reads a report written by Save
+func ReadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// Comparison is the change of a benchmark's mean time from its baseline
+type Comparison struct {
+	Name       string
+	Baseline   Result
+	Current    Result
+	Change     float64 // Relative change of the mean: 0.25 is 25% slower
+	Regression bool
+}
+
+// Compare compares results with a baseline. A benchmark regresses when
+// its mean is slower by more than the threshold and the slowdown exceeds
+// the noise, the standard deviations of both runs. Benchmarks missing
+// from the baseline, or failing in either run, are not compared.
+func Compare(baseline *Report, results []Result, threshold float64) []Comparison {
+	previous := make(map[string]Result, len(baseline.Results))
+	for _, result := range baseline.Results {
+		previous[result.Name] = result
+	}
+
+	var comparisons []Comparison
+	for _, current := range results {
+		base, ok := previous[current.Name]
+		if !ok || base.Error != "" || current.Error != "" || base.Mean <= 0 {
+			continue
+		}
+		slowdown := current.Mean - base.Mean
+		change := float64(slowdown) / float64(base.Mean)
+		comparisons = append(comparisons, Comparison{
+			Name:       current.Name,
+			Baseline:   base,
+			Current:    current,
+			Change:     change,
+			Regression: change > threshold && slowdown > base.StdDev+current.StdDev,
+		})
+	}
+	return comparisons
+}
@@ -0,0 +1,219 @@
+// Package bench times PHP programs under php-go: the classic bench.php and
+// micro_bench.php workloads and array, string, object and deep recursion
+// micro-benchmarks it ships, or any script, and compares the timings with
+// a baseline to catch performance regressions.
+package bench
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/krizos/php-go/pkg/compiler"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ============================================================================
+// Benchmarks
+// ============================================================================
+
+// workloads holds the standard benchmarks, a directory per group. Their
+// sizes are reduced from bench.php so each run takes about a second, and
+// they keep to the syntax and functions php-go handles: explicit keys
+// rather than $a[] = ..., arrays returned rather than passed by reference.
+// TestStandard_Run checks the output of each.
+//
+//go:embed workloads
+var workloads embed.FS
+
+// Benchmark is a PHP program the harness times
+type Benchmark struct {
+	Name   string // Group and file name: "bench/fibo"
+	Path   string // Path the script runs as
+	Source []byte
+}
+
+// Standard returns the benchmarks shipped with php-go, by name
+func Standard() []Benchmark {
+	var benchmarks []Benchmark
+	fs.WalkDir(workloads, "workloads", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || path.Ext(name) != ".php" {
+			return err
+		}
+		source, err := workloads.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		benchmarks = append(benchmarks, Benchmark{
+			Name:   strings.TrimSuffix(strings.TrimPrefix(name, "workloads/"), ".php"),
+			Path:   path.Base(name),
+			Source: source,
+		})
+		return nil
+	})
+	sort.Slice(benchmarks, func(i, j int) bool { return benchmarks[i].Name < benchmarks[j].Name })
+	return benchmarks
+}
+
+// Load reads the benchmarks of scripts, and of the .php files of
+// directories, named after the files
+func Load(paths []string) ([]Benchmark, error) {
+	var benchmarks []Benchmark
+	add := func(file string) error {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		benchmarks = append(benchmarks, Benchmark{
+			Name:   strings.TrimSuffix(filepath.ToSlash(file), ".php"),
+			Path:   file,
+			Source: source,
+		})
+		return nil
+	}
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if err := add(p); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		err = filepath.WalkDir(p, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() || filepath.Ext(file) != ".php" {
+				return err
+			}
+			return add(file)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return benchmarks, nil
+}
+
+// Filter returns the benchmarks whose names contain one of the patterns,
+// all of them without patterns
+func Filter(benchmarks []Benchmark, patterns []string) []Benchmark {
+	if len(patterns) == 0 {
+		return benchmarks
+	}
+	var selected []Benchmark
+	for _, b := range benchmarks {
+		for _, pattern := range patterns {
+			if strings.Contains(b.Name, pattern) {
+				selected = append(selected, b)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// ============================================================================
+// Running
+// ============================================================================
+
+// Options are the settings of a benchmark run
+type Options struct {
+	Iterations int // Timed runs of each benchmark
+	Warmup     int // Untimed runs before them
+	Level      compiler.OptimizationLevel
+	Output     io.Writer // Receives the output of the script; discarded when nil
+}
+
+// Result holds the timings of a benchmark. A benchmark that fails has the
+// error and no timings.
+type Result struct {
+	Name       string        `json:"name"`
+	Iterations int           `json:"iterations"`
+	Mean       time.Duration `json:"mean_ns"`
+	StdDev     time.Duration `json:"stddev_ns"`
+	Min        time.Duration `json:"min_ns"`
+	Max        time.Duration `json:"max_ns"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Run times a benchmark: it compiles and runs the script in a new VM for
+// each warmup and timed run, timing only the execution. The output of
+// every run goes to opts.Output.
+func Run(ctx context.Context, b Benchmark, opts Options) Result {
+	result := Result{Name: b.Name}
+	compile := compiler.ScriptCompiler(opts.Level)
+	output := opts.Output
+	if output == nil {
+		output = io.Discard
+	}
+	var times []time.Duration
+	for i := 0; i < opts.Warmup+opts.Iterations; i++ {
+		elapsed, err := runOnce(ctx, compile, b, output)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if i >= opts.Warmup {
+			times = append(times, elapsed)
+		}
+	}
+	result.Iterations = len(times)
+	result.Mean, result.StdDev, result.Min, result.Max = summarize(times)
+	return result
+}
+
+// runOnce compiles a benchmark and times a run of it
+func runOnce(ctx context.Context, compile vm.ScriptCompiler, b Benchmark, output io.Writer) (time.Duration, error) {
+	script, err := compile(b.Path, b.Source)
+	if err != nil {
+		return 0, err
+	}
+	machine := vm.New()
+	machine.SetContext(ctx)
+	machine.SetScriptCompiler(compile)
+	machine.SetOutputWriter(output)
+	start := time.Now()
+	err = machine.ExecuteScript(script)
+	elapsed := time.Since(start)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err == nil && machine.ExitStatus() != 0 {
+		err = fmt.Errorf("exit status %d", machine.ExitStatus())
+	}
+	return elapsed, err
+}
+
+// summarize returns the mean, sample standard deviation, minimum and
+// maximum of timings
+func summarize(times []time.Duration) (mean, stddev, fastest, slowest time.Duration) {
+	if len(times) == 0 {
+		return 0, 0, 0, 0
+	}
+	var sum float64
+	fastest, slowest = times[0], times[0]
+	for _, t := range times {
+		sum += float64(t)
+		fastest = min(fastest, t)
+		slowest = max(slowest, t)
+	}
+	avg := sum / float64(len(times))
+	if len(times) > 1 {
+		var squares float64
+		for _, t := range times {
+			squares += (float64(t) - avg) * (float64(t) - avg)
+		}
+		stddev = time.Duration(math.Sqrt(squares / float64(len(times)-1)))
+	}
+	return time.Duration(avg), stddev, fastest, slowest
+}
@@ -0,0 +1,166 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/krizos/php-go/pkg/compiler"
+)
+
+func TestStandard_Compile(t *testing.T) {
+	benchmarks := Standard()
+	if len(benchmarks) < 20 {
+		t.Fatalf("Expected the standard benchmarks, got %d", len(benchmarks))
+	}
+	compile := compiler.ScriptCompiler(compiler.OptimizeNone)
+	for _, b := range benchmarks {
+		if _, err := compile(b.Path, b.Source); err != nil {
+			t.Errorf("%s does not compile: %v", b.Name, err)
+		}
+	}
+	if len(Filter(benchmarks, []string{"bench/fibo", "micro/"})) != 7 {
+		t.Errorf("Expected bench/fibo and the 6 micro-benchmarks, got %v", Filter(benchmarks, []string{"bench/fibo", "micro/"}))
+	}
+}
+
+// standardOutput is the output of each standard benchmark
+var standardOutput = map[string]string{
+	"array/assoc":         "5000 4999950000\n",
+	"array/push_sum":      "40000000000\n",
+	"bench/ackermann":     "Ack(3,6): 509\n",
+	"bench/ary":           "49999\n",
+	"bench/fibo":          "121393\n",
+	"bench/hash1":         "50000\n",
+	"bench/heapsort":      "0.9999857110\n",
+	"bench/mandel":        "1595\n",
+	"bench/matrix":        "270165 1061760 1453695 1856025\n",
+	"bench/nestedloop":    "1000000\n",
+	"bench/sieve":         "Count: 1028\n",
+	"bench/simple":        "2000000\n",
+	"bench/simplecall":    "5000000\n",
+	"bench/simpleucall":   "1000000\n",
+	"bench/strcat":        "120000\n",
+	"micro/empty_loop":    "1000000\n",
+	"micro/func_call":     "500000\n",
+	"micro/isset":         "500000\n",
+	"micro/locals":        "4000000\n",
+	"micro/method_call":   "500000\n",
+	"micro/properties":    "1000000\n",
+	"object/create":       "1249975000 50000\n",
+	"object/dispatch":     "538052 600000\n",
+	"recursion/factorial": "7.257415615308E+306\n",
+	"recursion/fib_tail":  "976496506\n",
+	"recursion/tree":      "16384\n",
+	"string/format":       "123,456.79 item 99999 is 99999:14285.57:1869f\n",
+}
+
+func TestStandard_Run(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Running every standard benchmark takes about 20s")
+	}
+	benchmarks := Standard()
+	if len(benchmarks) != len(standardOutput) {
+		t.Errorf("Expected %d benchmarks, got %d", len(standardOutput), len(benchmarks))
+	}
+	for _, b := range benchmarks {
+		var out bytes.Buffer
+		result := Run(context.Background(), b, Options{Iterations: 1, Output: &out})
+		if result.Error != "" {
+			t.Errorf("%s failed: %s\noutput: %s", b.Name, result.Error, out.String())
+			continue
+		}
+		if expected, ok := standardOutput[b.Name]; !ok || out.String() != expected {
+			t.Errorf("%s: expected output %q, got %q", b.Name, expected, out.String())
+		}
+	}
+}
+
+func TestStandard_RunRecursionOptimized(t *testing.T) {
+	for _, b := range Standard() {
+		if filepath.Dir(b.Name) != "recursion" {
+			continue
+		}
+		var out bytes.Buffer
+		result := Run(context.Background(), b, Options{Iterations: 1, Level: compiler.OptimizePeephole, Output: &out})
+		if result.Error != "" {
+			t.Errorf("%s failed at -O1: %s", b.Name, result.Error)
+			continue
+		}
+		if expected := standardOutput[b.Name]; out.String() != expected {
+			t.Errorf("%s: expected output %q at -O1, got %q", b.Name, expected, out.String())
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	b := Benchmark{Name: "echo", Path: "echo.php", Source: []byte(`<?php echo "hello";`)}
+	result := Run(context.Background(), b, Options{Iterations: 3, Warmup: 1})
+	if result.Error != "" || result.Iterations != 3 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	if result.Min <= 0 || result.Min > result.Mean || result.Mean > result.Max {
+		t.Errorf("Expected min <= mean <= max, got %+v", result)
+	}
+
+	failing := Benchmark{Name: "fatal", Path: "fatal.php", Source: []byte(`<?php throw new Exception("boom");`)}
+	if result := Run(context.Background(), failing, Options{Iterations: 3}); result.Error == "" || result.Iterations != 0 {
+		t.Errorf("Expected the failure of the script, got %+v", result)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	mean, stddev, fastest, slowest := summarize([]time.Duration{2, 4, 4, 4, 5, 5, 7, 9})
+	if mean != 5 || fastest != 2 || slowest != 9 {
+		t.Errorf("Unexpected summary %v %v %v", mean, fastest, slowest)
+	}
+	// Sample standard deviation: sqrt(32/7)
+	if stddev != 2 {
+		t.Errorf("Expected a standard deviation of 2ns, got %v", stddev)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := &Report{Results: []Result{
+		{Name: "fast", Mean: 100 * time.Millisecond, StdDev: time.Millisecond},
+		{Name: "noisy", Mean: 100 * time.Millisecond, StdDev: 20 * time.Millisecond},
+		{Name: "same", Mean: 100 * time.Millisecond, StdDev: time.Millisecond},
+		{Name: "broken", Error: "boom"},
+	}}
+	comparisons := Compare(baseline, []Result{
+		{Name: "fast", Mean: 120 * time.Millisecond, StdDev: time.Millisecond},
+		{Name: "noisy", Mean: 120 * time.Millisecond, StdDev: 5 * time.Millisecond},
+		{Name: "same", Mean: 105 * time.Millisecond, StdDev: time.Millisecond},
+		{Name: "broken", Mean: time.Millisecond},
+		{Name: "new", Mean: time.Millisecond},
+	}, DefaultThreshold)
+
+	if len(comparisons) != 3 {
+		t.Fatalf("Expected 3 comparisons, got %+v", comparisons)
+	}
+	for i, expected := range []bool{true, false, false} {
+		if comparisons[i].Regression != expected {
+			t.Errorf("Expected %s regression=%v, got %+v", comparisons[i].Name, expected, comparisons[i])
+		}
+	}
+	if change := comparisons[0].Change; change < 0.199 || change > 0.201 {
+		t.Errorf("Expected a 20%% slowdown, got %v", change)
+	}
+}
+
+func TestReport_SaveRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	results := []Result{{Name: "bench/fibo", Iterations: 10, Mean: 3 * time.Millisecond}}
+	if err := NewReport(Options{Iterations: 10, Warmup: 2}, results).Save(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	report, err := ReadReport(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Iterations != 10 || len(report.Results) != 1 || report.Results[0].Mean != 3*time.Millisecond {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
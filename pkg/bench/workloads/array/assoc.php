@@ -0,0 +1,17 @@
+<?php
+// Associative arrays: string keys, lookups and iteration by key
+$map = [];
+for ($i = 0; $i < 100000; $i++) {
+    $map["key" . ($i % 5000)] = ($map["key" . ($i % 5000)] ?? 0) + $i;
+}
+$count = 0;
+$total = 0;
+foreach ($map as $key => $value) {
+    $count++;
+    if (array_key_exists($key, $map)) {
+        if (isset($map[$key])) {
+            $total += $value;
+        }
+    }
+}
+echo $count, " ", $total, "\n";
@@ -0,0 +1,11 @@
+<?php
+// Filling arrays and summing them
+$values = [];
+for ($i = 0; $i < 200000; $i++) {
+    $values[$i] = $i;
+}
+$sum = 0;
+foreach ($values as $value) {
+    $sum += $value;
+}
+echo $sum + array_sum($values) + $i, "\n";
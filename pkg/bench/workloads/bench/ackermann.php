@@ -0,0 +1,15 @@
+<?php
+// Deep recursion (bench.php ackermann)
+function Ack($m, $n)
+{
+    if ($m == 0) {
+        return $n + 1;
+    }
+    if ($n == 0) {
+        return Ack($m - 1, 1);
+    }
+    return Ack($m - 1, Ack($m, ($n - 1)));
+}
+
+$n = 6;
+echo "Ack(3,$n): " . Ack(3, $n) . "\n";
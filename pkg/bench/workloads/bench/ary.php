@@ -0,0 +1,13 @@
+<?php
+// Copying an array element by element (bench.php ary)
+$n = 50000;
+$X = [];
+$Y = [];
+for ($i = 0; $i < $n; $i++) {
+    $X[$i] = $i;
+}
+for ($i = $n - 1; $i >= 0; $i--) {
+    $Y[$i] = $X[$i];
+}
+$last = $n - 1;
+echo "$Y[$last]\n";
@@ -0,0 +1,10 @@
+<?php
+// Recursive calls (bench.php fibo)
+function fibo($n)
+{
+    return ($n < 2) ? 1 : fibo($n - 2) + fibo($n - 1);
+}
+
+$n = 25;
+$r = fibo($n);
+echo "$r\n";
@@ -0,0 +1,14 @@
+<?php
+// String keys built from numbers (bench.php hash1)
+$n = 50000;
+$X = [];
+for ($i = 1; $i <= $n; $i++) {
+    $X[dechex($i)] = $i;
+}
+$c = 0;
+for ($i = $n; $i > 0; $i--) {
+    if ($X[dechex($i)]) {
+        $c++;
+    }
+}
+echo "$c\n";
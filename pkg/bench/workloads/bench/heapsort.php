@@ -0,0 +1,51 @@
+<?php
+// Heap sort of pseudo-random floats (bench.php heapsort)
+function gen_random($n)
+{
+    global $LAST;
+    return (($n * ($LAST = ($LAST * 3877 + 29573) % 139968)) / 139968);
+}
+
+function heapsort_r($n, $ra)
+{
+    $l = ($n >> 1) + 1;
+    $ir = $n;
+
+    while (1) {
+        if ($l > 1) {
+            $rra = $ra[--$l];
+        } else {
+            $rra = $ra[$ir];
+            $ra[$ir] = $ra[1];
+            if (--$ir == 1) {
+                $ra[1] = $rra;
+                return $ra;
+            }
+        }
+        $i = $l;
+        $j = $l << 1;
+        while ($j <= $ir) {
+            if ($j < $ir) {
+                if ($ra[$j] < $ra[$j + 1]) {
+                    $j++;
+                }
+            }
+            if ($rra < $ra[$j]) {
+                $ra[$i] = $ra[$j];
+                $j += ($i = $j);
+            } else {
+                $j = $ir + 1;
+            }
+        }
+        $ra[$i] = $rra;
+    }
+}
+
+$LAST = 42;
+$n = 20000;
+$ary = [0];
+for ($i = 1; $i <= $n; $i++) {
+    $ary[$i] = gen_random(1);
+}
+$ary = heapsort_r($n, $ary);
+printf("%.10f\n", $ary[$n]);
@@ -0,0 +1,40 @@
+<?php
+// Matrix multiplication (bench.php matrix)
+function mkmatrix($rows, $cols)
+{
+    $count = 1;
+    $mx = [];
+    for ($i = 0; $i < $rows; $i++) {
+        $row = [];
+        for ($j = 0; $j < $cols; $j++) {
+            $row[$j] = $count++;
+        }
+        $mx[$i] = $row;
+    }
+    return $mx;
+}
+
+function mmult($rows, $cols, $m1, $m2)
+{
+    $m3 = [];
+    for ($i = 0; $i < $rows; $i++) {
+        $row = [];
+        for ($j = 0; $j < $cols; $j++) {
+            $x = 0;
+            for ($k = 0; $k < $cols; $k++) {
+                $x += $m1[$i][$k] * $m2[$k][$j];
+            }
+            $row[$j] = $x;
+        }
+        $m3[$i] = $row;
+    }
+    return $m3;
+}
+
+$SIZE = 30;
+$m1 = mkmatrix($SIZE, $SIZE);
+$m2 = mkmatrix($SIZE, $SIZE);
+for ($n = 0; $n < 20; $n++) {
+    $mm = mmult($SIZE, $SIZE, $m1, $m2);
+}
+echo "{$mm[0][0]} {$mm[2][3]} {$mm[3][2]} {$mm[4][4]}\n";
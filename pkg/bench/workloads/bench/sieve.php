@@ -0,0 +1,17 @@
+<?php
+// Sieve of Eratosthenes (bench.php sieve)
+$n = 30;
+$primes = 0;
+while ($n-- > 0) {
+    $primes = 0;
+    $flags = range(0, 8192);
+    for ($i = 2; $i < 8193; $i++) {
+        if ($flags[$i] > 0) {
+            for ($k = $i + $i; $k <= 8192; $k += $i) {
+                $flags[$k] = 0;
+            }
+            $primes++;
+        }
+    }
+}
+echo "Count: $primes\n";
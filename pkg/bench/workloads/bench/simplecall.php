@@ -0,0 +1,7 @@
+<?php
+// Calls of an internal function (bench.php simplecall)
+$x = 0;
+for ($i = 0; $i < 1000000; $i++) {
+    $x += strlen("hallo");
+}
+echo $x, "\n";
@@ -0,0 +1,10 @@
+<?php
+// Calls of a user function (bench.php simpleucall)
+function hallo($a)
+{
+}
+
+for ($i = 0; $i < 1000000; $i++) {
+    hallo("hallo");
+}
+echo $i, "\n";
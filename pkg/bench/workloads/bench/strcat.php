@@ -0,0 +1,9 @@
+<?php
+// Appending to a string (bench.php strcat)
+$n = 20000;
+$str = "";
+while ($n-- > 0) {
+    $str .= "hello\n";
+}
+$len = strlen($str);
+echo "$len\n";
@@ -0,0 +1,6 @@
+<?php
+// The cost of a loop, the baseline of the other micro-benchmarks
+// (micro_bench.php empty_loop)
+for ($i = 0; $i < 1000000; ++$i) {
+}
+echo $i, "\n";
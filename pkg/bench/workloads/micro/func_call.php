@@ -0,0 +1,17 @@
+<?php
+// Calls of user functions with and without arguments (micro_bench.php
+// func_call)
+function f0()
+{
+}
+
+function f2($a, $b)
+{
+    return $a;
+}
+
+for ($i = 0; $i < 500000; ++$i) {
+    f0();
+    f2($i, 1);
+}
+echo $i, "\n";
@@ -0,0 +1,22 @@
+<?php
+// isset() and empty() on variables, array elements and properties
+// (micro_bench.php isset_*, empty_*)
+class Holder
+{
+    public $x = 1;
+}
+
+$a = ["x" => 1];
+$o = new Holder();
+$v = 1;
+$hits = 0;
+for ($i = 0; $i < 500000; ++$i) {
+    if (isset($v, $a["x"])) {
+        if (!empty($o->x)) {
+            if (!isset($a["y"])) {
+                $hits++;
+            }
+        }
+    }
+}
+echo $hits, "\n";
@@ -0,0 +1,18 @@
+<?php
+// Local variables: assignment, increment and compound assignment
+// (micro_bench.php assign_add, pre_inc, post_inc)
+function locals($n)
+{
+    $a = 0;
+    $b = 0;
+    $c = 1;
+    for ($i = 0; $i < $n; ++$i) {
+        $a += $c;
+        $b++;
+        ++$b;
+        $c = $b - $a;
+    }
+    return $a + $b + $c;
+}
+
+echo locals(1000000), "\n";
@@ -0,0 +1,20 @@
+<?php
+// Calls of instance and static methods (micro_bench.php method_call and
+// static_method)
+class Foo
+{
+    public function f()
+    {
+    }
+
+    public static function g()
+    {
+    }
+}
+
+$x = new Foo();
+for ($i = 0; $i < 500000; ++$i) {
+    $x->f();
+    Foo::g();
+}
+echo $i, "\n";
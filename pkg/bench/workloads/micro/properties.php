@@ -0,0 +1,16 @@
+<?php
+// Reading and writing properties, static properties and constants
+// (micro_bench.php read_prop, write_prop, read_static_prop, read_const)
+class Foo
+{
+    const TEST = 0;
+    public static $s = 0;
+    public $a = 0;
+}
+
+$x = new Foo();
+for ($i = 0; $i < 500000; ++$i) {
+    $x->a = $x->a + 1;
+    Foo::$s = Foo::$s + Foo::TEST + 1;
+}
+echo $x->a + Foo::$s, "\n";
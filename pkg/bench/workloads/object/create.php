@@ -0,0 +1,24 @@
+<?php
+// Creating and destroying objects with constructors
+class Point
+{
+    public float $x;
+    public float $y;
+
+    public function __construct(float $x = 0.0, float $y = 0.0)
+    {
+        $this->x = $x;
+        $this->y = $y;
+    }
+
+    public function add(Point $other): Point
+    {
+        return new Point($this->x + $other->x, $this->y + $other->y);
+    }
+}
+
+$sum = new Point();
+for ($i = 0; $i < 50000; $i++) {
+    $sum = $sum->add(new Point($i, 1));
+}
+echo $sum->x, " ", $sum->y, "\n";
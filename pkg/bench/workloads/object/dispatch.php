@@ -0,0 +1,54 @@
+<?php
+// Calls through interfaces and inherited and overridden methods
+interface Shape
+{
+    public function area(): float;
+}
+
+abstract class Base implements Shape
+{
+    public function describe(): string
+    {
+        return static::class;
+    }
+}
+
+class Square extends Base
+{
+    private float $side;
+
+    public function __construct(float $side)
+    {
+        $this->side = $side;
+    }
+
+    public function area(): float
+    {
+        return $this->side * $this->side;
+    }
+}
+
+class Circle extends Base
+{
+    private float $radius;
+
+    public function __construct(float $radius)
+    {
+        $this->radius = $radius;
+    }
+
+    public function area(): float
+    {
+        return M_PI * $this->radius * $this->radius;
+    }
+}
+
+$shapes = [new Square(2), new Circle(1), new Square(3)];
+$area = 0.0;
+$names = 0;
+for ($i = 0; $i < 100000; $i++) {
+    $shape = $shapes[$i % 3];
+    $area += $shape->area();
+    $names += strlen($shape->describe());
+}
+echo round($area), " ", $names, "\n";
@@ -0,0 +1,16 @@
+<?php
+// Tail-recursive factorial, run in one frame when the optimizer marks
+// the tail call (-O1)
+function fact($n, $acc)
+{
+    if ($n < 2) {
+        return $acc;
+    }
+    return fact($n - 1, $acc * $n);
+}
+
+$r = 0;
+for ($i = 0; $i < 2000; $i++) {
+    $r = fact(170, 1.0);
+}
+echo "$r\n";
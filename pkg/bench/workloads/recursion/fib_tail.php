@@ -0,0 +1,17 @@
+<?php
+// Tail-recursive fib, 5000 calls deep without tail call elimination
+ini_set('xdebug.max_nesting_level', '10000');
+
+function fib($n, $a, $b)
+{
+    if ($n < 1) {
+        return $a;
+    }
+    return fib($n - 1, $b, ($a + $b) % 1000000007);
+}
+
+$r = 0;
+for ($i = 0; $i < 100; $i++) {
+    $r = fib(5000, 0, 1);
+}
+echo "$r\n";
@@ -0,0 +1,26 @@
+<?php
+// Building and walking a binary tree of nested arrays
+function build($depth)
+{
+    if ($depth < 1) {
+        return 1;
+    }
+    $next = $depth - 1;
+    return [0 => build($next), 1 => build($next)];
+}
+
+function walk($tree, $depth)
+{
+    if ($depth < 1) {
+        return $tree;
+    }
+    $next = $depth - 1;
+    return walk($tree[0], $next) + walk($tree[1], $next);
+}
+
+$tree = build(14);
+$sum = 0;
+for ($i = 0; $i < 10; $i++) {
+    $sum = walk($tree, 14);
+}
+echo "$sum\n";
@@ -0,0 +1,8 @@
+<?php
+// Formatting numbers into strings and interpolating variables
+$out = "";
+for ($i = 0; $i < 100000; $i++) {
+    $line = sprintf("%05d:%.2f:%s", $i, $i / 7, dechex($i));
+    $out = "item {$i} is $line";
+}
+echo number_format(123456.789, 2), " ", $out, "\n";
@@ -0,0 +1,328 @@
+// Package bundle packs a PHP application into a single file, as phar
+// archives do: the entry script, the files it includes and any others
+// named when bundling, in a zip archive with a manifest. Running a bundle
+// mounts its files in the VM under the path of the bundle itself, so
+// __DIR__ of the entry script is the bundle path and includes resolve in
+// the bundle ahead of the disk.
+package bundle
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/compiler"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ManifestName is the name of the manifest in the archive
+const ManifestName = ".php-go-bundle.json"
+
+// FormatVersion is the version of the bundle format Create writes
+const FormatVersion = 1
+
+// Manifest describes the contents of a bundle
+type Manifest struct {
+	Version           int                        `json:"version"`
+	Entry             string                     `json:"entry"`    // Slash-separated path of the entry script in the archive
+	Files             []string                   `json:"files"`    // Bundled files, sorted
+	Compiled          bool                       `json:"compiled"` // Every PHP file was compiled when bundling
+	OptimizationLevel compiler.OptimizationLevel `json:"optimization_level"`
+}
+
+// ============================================================================
+// Creating Bundles
+// ============================================================================
+
+// Options are the settings of Create
+type Options struct {
+	// Extra names files and directories bundled in addition to those the
+	// entry script includes, such as files loaded by an autoloader or
+	// includes of computed paths
+	Extra []string
+
+	// Compile compiles each PHP file when bundling, so a bundle of code
+	// that does not compile is refused
+	Compile bool
+
+	// OptimizationLevel is the level files are compiled at, when bundling
+	// and when the bundle runs
+	OptimizationLevel compiler.OptimizationLevel
+}
+
+// Create writes a bundle of an entry script, the files it includes,
+// transitively, and the extra files of the options. It returns the
+// manifest and warnings about includes it could not follow, whose files
+// must be added as extra files.
+func Create(w io.Writer, entry string, opts Options) (*Manifest, []string, error) {
+	entry, err := filepath.Abs(entry)
+	if err != nil {
+		return nil, nil, err
+	}
+	collected, warnings, err := collect(entry, opts.Extra)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths := make([]string, 0, len(collected))
+	for p := range collected {
+		paths = append(paths, p)
+	}
+	base := commonDir(paths)
+
+	manifest := &Manifest{
+		Version:           FormatVersion,
+		Entry:             archiveName(base, entry),
+		Compiled:          opts.Compile,
+		OptimizationLevel: opts.OptimizationLevel,
+	}
+	for _, p := range paths {
+		manifest.Files = append(manifest.Files, archiveName(base, p))
+	}
+	sort.Strings(manifest.Files)
+
+	if opts.Compile {
+		compile := compiler.ScriptCompiler(opts.OptimizationLevel)
+		for _, p := range paths {
+			if !isPHPFile(p) && p != entry {
+				continue
+			}
+			if _, err := compile(p, collected[p]); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", p, err)
+			}
+		}
+	}
+
+	archive := zip.NewWriter(w)
+	header, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writeFile(archive, ManifestName, header); err != nil {
+		return nil, nil, err
+	}
+	for _, name := range manifest.Files {
+		if err := writeFile(archive, name, collected[filepath.Join(base, filepath.FromSlash(name))]); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, nil, err
+	}
+	return manifest, warnings, nil
+}
+
+// writeFile adds a compressed file to the archive
+func writeFile(archive *zip.Writer, name string, content []byte) error {
+	file, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	return err
+}
+
+// collect reads the entry script and extra files, following the includes
+// of each PHP file, and returns their contents by absolute path
+func collect(entry string, extra []string) (map[string][]byte, []string, error) {
+	files := make(map[string][]byte)
+	var warnings []string
+	queue := []string{entry}
+
+	for _, name := range extra {
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = filepath.WalkDir(abs, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				queue = append(queue, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if _, done := files[p]; done {
+			continue
+		}
+		source, err := os.ReadFile(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		files[p] = source
+		if !isPHPFile(p) && p != entry {
+			continue
+		}
+
+		found, unresolved := includes(p, source)
+		for _, inc := range found {
+			resolved, ok := resolveInclude(p, inc.path)
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("%s:%d: included file %s not found", p, inc.line, inc.path))
+				continue
+			}
+			queue = append(queue, resolved)
+		}
+		for _, line := range unresolved {
+			warnings = append(warnings, fmt.Sprintf("%s:%d: cannot follow an include of a computed path", p, line))
+		}
+	}
+	return files, warnings, nil
+}
+
+// resolveInclude finds the file an include names, as the VM searches for
+// it: relative paths in the directory of the including file, then the
+// working directory
+func resolveInclude(from, name string) (string, bool) {
+	candidates := []string{name}
+	if !filepath.IsAbs(name) {
+		candidates = []string{filepath.Join(filepath.Dir(from), name), name}
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+			abs, err := filepath.Abs(candidate)
+			if err != nil {
+				return "", false
+			}
+			return abs, true
+		}
+	}
+	return "", false
+}
+
+// isPHPFile reports whether a file holds PHP code whose includes are
+// followed
+func isPHPFile(p string) bool {
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".php", ".inc", ".phtml":
+		return true
+	}
+	return false
+}
+
+// commonDir returns the deepest directory containing all paths
+func commonDir(paths []string) string {
+	base := ""
+	for _, p := range paths {
+		dir := filepath.Dir(p)
+		if base == "" {
+			base = dir
+			continue
+		}
+		for !strings.HasPrefix(dir+string(filepath.Separator), strings.TrimSuffix(base, string(filepath.Separator))+string(filepath.Separator)) {
+			base = filepath.Dir(base)
+		}
+	}
+	return base
+}
+
+// archiveName returns the slash-separated name of a file in the archive
+func archiveName(base, p string) string {
+	rel, err := filepath.Rel(base, p)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// ============================================================================
+// Running Bundles
+// ============================================================================
+
+// Bundle is an open bundle
+type Bundle struct {
+	Manifest
+	path   string // Absolute path of the bundle
+	reader *zip.ReadCloser
+}
+
+// IsBundle reports whether a file is a bundle: a zip archive with a
+// manifest
+func IsBundle(p string) bool {
+	reader, err := zip.OpenReader(p)
+	if err != nil {
+		return false
+	}
+	defer reader.Close()
+	_, err = reader.Open(ManifestName)
+	return err == nil
+}
+
+// Open opens a bundle
+func Open(p string) (*Bundle, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := zip.OpenReader(abs)
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{path: abs, reader: reader}
+
+	data, err := fs.ReadFile(reader, ManifestName)
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("%s is not a bundle: %w", p, err)
+	}
+	if err := json.Unmarshal(data, &b.Manifest); err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("%s: invalid manifest: %w", p, err)
+	}
+	if b.Version > FormatVersion {
+		reader.Close()
+		return nil, fmt.Errorf("%s: bundle format %d is newer than this version supports", p, b.Version)
+	}
+	return b, nil
+}
+
+// Close closes the bundle
+func (b *Bundle) Close() error {
+	return b.reader.Close()
+}
+
+// Root returns the directory the bundled files appear in when the bundle
+// runs: the path of the bundle
+func (b *Bundle) Root() string {
+	return b.path
+}
+
+// EntryPath returns the path of the entry script when the bundle runs
+func (b *Bundle) EntryPath() string {
+	return filepath.Join(b.path, filepath.FromSlash(b.Entry))
+}
+
+// FS returns the bundled files
+func (b *Bundle) FS() fs.FS {
+	return b.reader
+}
+
+// Mount serves the bundled files to include and require in a VM
+func (b *Bundle) Mount(machine *vm.VM) {
+	machine.Mount(b.path, b.reader)
+}
+
+// Script compiles the entry script
+func (b *Bundle) Script(compile vm.ScriptCompiler) (*vm.Script, error) {
+	source, err := fs.ReadFile(b.reader, path.Clean(b.Entry))
+	if err != nil {
+		return nil, err
+	}
+	return compile(b.EntryPath(), source)
+}
@@ -0,0 +1,136 @@
+package bundle
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/compiler"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// writeFiles creates files under a directory
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	file := filepath.FromSlash("/app/src/index.php")
+	source := `<?php
+require_once __DIR__ . '/lib/a.php';
+include 'b.php';
+require(dirname(__FILE__) . "/c.php");
+echo "include";
+include $path;
+require_once __DIR__ . "/$name.php";
+`
+	found, unresolved := includes(file, []byte(source))
+
+	expected := []include{
+		{path: filepath.FromSlash("/app/src") + "/lib/a.php", line: 2},
+		{path: "b.php", line: 3},
+		{path: filepath.FromSlash("/app/src") + "/c.php", line: 4},
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("Expected includes %v, got %v", expected, found)
+	}
+	if !reflect.DeepEqual(unresolved, []int{6, 7}) {
+		t.Errorf("Expected computed includes on lines [6 7], got %v", unresolved)
+	}
+}
+
+func TestCreateAndOpen(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app/index.php":      "<?php require_once __DIR__ . '/lib/a.php'; include 'views/page.php'; include $x;",
+		"app/lib/a.php":      "<?php require 'b.php';",
+		"app/lib/b.php":      "<?php echo 'b';",
+		"app/views/page.php": "<?php echo 'page';",
+		"app/data/plain.txt": "data",
+		"app/unused.php":     "<?php echo 'unused';",
+	})
+
+	var buf bytes.Buffer
+	manifest, warnings, err := Create(&buf, filepath.Join(dir, "app", "index.php"), Options{
+		Extra: []string{filepath.Join(dir, "app", "data")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files := []string{"data/plain.txt", "index.php", "lib/a.php", "lib/b.php", "views/page.php"}
+	if manifest.Entry != "index.php" || !reflect.DeepEqual(manifest.Files, files) {
+		t.Errorf("Expected entry index.php and files %v, got %s and %v", files, manifest.Entry, manifest.Files)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "index.php:1: cannot follow") {
+		t.Errorf("Expected a warning about the computed include, got %v", warnings)
+	}
+
+	path := filepath.Join(dir, "app.bundle")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !IsBundle(path) || IsBundle(filepath.Join(dir, "app", "index.php")) {
+		t.Error("IsBundle should recognize bundles only")
+	}
+
+	b, err := Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer b.Close()
+	if !reflect.DeepEqual(b.Files, files) || b.Version != FormatVersion {
+		t.Errorf("Expected the manifest to round-trip, got %+v", b.Manifest)
+	}
+	if b.EntryPath() != filepath.Join(path, "index.php") {
+		t.Errorf("Expected the entry inside the bundle path, got %s", b.EntryPath())
+	}
+
+	machine := vm.New()
+	b.Mount(machine)
+	script, err := b.Script(compiler.ScriptCompiler(compiler.OptimizeNone))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if script.Path != b.EntryPath() {
+		t.Errorf("Expected the script path %s, got %s", b.EntryPath(), script.Path)
+	}
+}
+
+func TestCreate_Compile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"index.php":  "<?php require 'broken.php';",
+		"broken.php": "<?php function (",
+	})
+
+	_, _, err := Create(&bytes.Buffer{}, filepath.Join(dir, "index.php"), Options{Compile: true})
+	if err == nil || !strings.Contains(err.Error(), "broken.php") {
+		t.Errorf("Expected bundling to fail on broken.php, got %v", err)
+	}
+
+	manifest, _, err := Create(&bytes.Buffer{}, filepath.Join(dir, "index.php"), Options{})
+	if err != nil || len(manifest.Files) != 2 || manifest.Compiled {
+		t.Errorf("Expected an uncompiled bundle of both files, got %+v, %v", manifest, err)
+	}
+}
+
+func TestOpen_NotABundle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.php")
+	writeFiles(t, filepath.Dir(path), map[string]string{"index.php": "<?php"})
+	if _, err := Open(path); err == nil {
+		t.Error("Expected an error opening a file that is not a bundle")
+	}
+}
@@ -0,0 +1,105 @@
+package bundle
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+// ============================================================================
+// Include Discovery
+// ============================================================================
+
+// include is an include or require of a constant path
+type include struct {
+	path string
+	line int
+}
+
+// includes scans a PHP file for include and require statements. The paths
+// of those whose expression is constant — string literals, __FILE__,
+// __DIR__ and dirname(__FILE__) joined by the concatenation operator — are
+// returned; the lines of the others are returned as unresolved.
+func includes(file string, source []byte) ([]include, []int) {
+	l := lexer.New(string(source), file)
+	var found []include
+	var unresolved []int
+
+	for {
+		tok := l.NextToken()
+		switch tok.Type {
+		case lexer.EOF:
+			return found, unresolved
+		case lexer.INCLUDE, lexer.INCLUDE_ONCE, lexer.REQUIRE, lexer.REQUIRE_ONCE:
+			if p, ok := constantPath(l, file); ok {
+				found = append(found, include{path: p, line: tok.Pos.Line})
+			} else {
+				unresolved = append(unresolved, tok.Pos.Line)
+			}
+		}
+	}
+}
+
+// constantPath evaluates the path expression following an include
+// keyword, reporting false if it is not constant. It consumes the tokens
+// up to the end of the expression.
+func constantPath(l *lexer.Lexer, file string) (string, bool) {
+	var path strings.Builder
+	depth := 0
+	constant, operand := true, false
+
+	for {
+		tok := l.NextToken()
+		switch tok.Type {
+		case lexer.EOF, lexer.SEMICOLON, lexer.CLOSE_TAG, lexer.COMMA:
+			return path.String(), constant && operand
+		case lexer.LPAREN:
+			depth++
+			continue
+		case lexer.RPAREN:
+			if depth == 0 {
+				return path.String(), constant && operand // include(...) as an argument
+			}
+			depth--
+			continue
+		}
+		if !constant {
+			continue // Skip the rest of the expression
+		}
+
+		switch tok.Type {
+		case lexer.STRING:
+			path.WriteString(tok.Literal)
+			operand = true
+		case lexer.FILE_CONST:
+			path.WriteString(file)
+			operand = true
+		case lexer.DIR_CONST:
+			path.WriteString(filepath.Dir(file))
+			operand = true
+		case lexer.CONCAT, lexer.DOT:
+			operand = false
+		case lexer.IDENT:
+			if !strings.EqualFold(tok.Literal, "dirname") || !dirnameOfFile(l) {
+				constant = false
+				continue
+			}
+			path.WriteString(filepath.Dir(file))
+			operand = true
+		default:
+			constant = false
+		}
+	}
+}
+
+// dirnameOfFile consumes the arguments of a dirname call, reporting
+// whether they are exactly (__FILE__)
+func dirnameOfFile(l *lexer.Lexer) bool {
+	for _, want := range []lexer.TokenType{lexer.LPAREN, lexer.FILE_CONST, lexer.RPAREN} {
+		if l.NextToken().Type != want {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,265 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// Call Arguments
+// ========================================
+
+// declareSignatures records the parameters of the functions declared at the
+// top level of a program (or of its namespace blocks), so calls appearing
+// before a declaration can be checked too
+func (c *Compiler) declareSignatures(stmts []ast.Stmt, namespace string) {
+	for _, stmt := range stmts {
+		switch node := stmt.(type) {
+		case *ast.NamespaceStatement:
+			namespace = node.Name
+			if node.Body != nil {
+				c.declareSignatures(node.Body.Statements, node.Name)
+				namespace = ""
+			}
+		case *ast.FunctionDeclaration:
+			c.declareSignature(NewNamespaceContext(namespace).prefix(node.Name.Value), node.Parameters)
+		}
+	}
+}
+
+// declareSignature records the parameters of a function by its fully
+// qualified name
+func (c *Compiler) declareSignature(name string, params []*ast.Parameter) {
+	if c.signatures == nil {
+		c.signatures = make(map[string][]*ast.Parameter)
+	}
+	c.signatures[strings.ToLower(name)] = params
+}
+
+// callSignature returns the parameters of the function a call by name
+// refers to, when it is declared in the code being compiled
+func (c *Compiler) callSignature(node *ast.CallExpression) ([]*ast.Parameter, bool) {
+	ident, ok := node.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	name, fallback := c.namespace.ResolveFunctionName(ident.Value)
+	if params, ok := c.signatures[strings.ToLower(strings.TrimPrefix(name, "\\"))]; ok {
+		return params, true
+	}
+	if fallback != "" {
+		params, ok := c.signatures[strings.ToLower(fallback)]
+		return params, ok
+	}
+	return nil, false
+}
+
+// checkArguments enforces PHP's rules on argument lists: positional
+// arguments come first, then unpacked ones, then named ones, and no name
+// is used twice. With a known signature (known is true), named arguments
+// must name a parameter not already passed by position.
+func checkArguments(args []ast.Expr, params []*ast.Parameter, known bool) error {
+	seenUnpack := false
+	positional := 0
+	names := make(map[string]bool)
+
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case *ast.SpreadExpression:
+			if len(names) > 0 {
+				return fmt.Errorf("cannot use argument unpacking after named arguments")
+			}
+			seenUnpack = true
+
+		case *ast.NamedArgument:
+			if names[arg.Name] {
+				return fmt.Errorf("duplicate named parameter $%s", arg.Name)
+			}
+			names[arg.Name] = true
+			if known {
+				if err := checkNamedArgument(arg.Name, params, positional); err != nil {
+					return err
+				}
+			}
+
+		default:
+			if len(names) > 0 {
+				return fmt.Errorf("cannot use positional argument after named argument")
+			}
+			if seenUnpack {
+				return fmt.Errorf("cannot use positional argument after argument unpacking")
+			}
+			positional++
+		}
+	}
+	return nil
+}
+
+// checkNamedArgument checks a named argument against a known signature.
+// Names matching no parameter are only accepted by variadic functions.
+func checkNamedArgument(name string, params []*ast.Parameter, positional int) error {
+	for i, param := range params {
+		if param.Variadic {
+			return nil
+		}
+		if param.Name.Name == name {
+			if i < positional {
+				return fmt.Errorf("named parameter $%s overwrites previous argument", name)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown named parameter $%s", name)
+}
+
+// argumentValue returns the expression passed by an argument
+func argumentValue(arg ast.Expr) ast.Expr {
+	switch arg := arg.(type) {
+	case *ast.SpreadExpression:
+		return arg.Value
+	case *ast.NamedArgument:
+		return arg.Value
+	}
+	return arg
+}
+
+// refParams reports which arguments of a call by name are passed by
+// reference, when the function is declared in the code being compiled or
+// is a builtin taking references; nil if unknown (the function may be
+// declared by another file)
+func (c *Compiler) refParams(node *ast.CallExpression) func(position int) bool {
+	if params, ok := c.callSignature(node); ok {
+		return func(position int) bool {
+			if position < len(params) {
+				return params[position].ByRef
+			}
+			last := len(params) - 1
+			return last >= 0 && params[last].Variadic && params[last].ByRef
+		}
+	}
+	ident, ok := node.Function.(*ast.Identifier)
+	if !ok {
+		return nil
+	}
+	name, fallback := c.namespace.ResolveFunctionName(ident.Value)
+	if fallback != "" {
+		name = fallback
+	}
+	if !vm.BuiltinTakesRefs(name) {
+		return nil
+	}
+	return func(position int) bool {
+		return vm.BuiltinTakesRef(name, position)
+	}
+}
+
+// compileArguments sends the arguments of the call initialized by the
+// preceding INIT_* opcode: SEND_VAL for positional ones, SEND_VAL with the
+// parameter name as Op2 for named ones and SEND_UNPACK for ...$args. The
+// VM maps named arguments onto parameters when the call is made.
+//
+// byRef tells which positions the function called takes by reference, if
+// known: variables sent to them are sent with SEND_REF and array elements
+// are fetched as references (FETCH_LIST_W) first. When unknown, variables
+// are sent with SEND_VAR_EX, passing them by reference if the function
+// turns out to take the parameter by reference.
+func (c *Compiler) compileArguments(args []ast.Expr, line uint32, byRef func(position int) bool) error {
+	sendVariable := func(position int) (vm.Opcode, bool) {
+		switch {
+		case byRef == nil:
+			return vm.OpSendVarEx, true
+		case byRef(position):
+			return vm.OpSendRef, true
+		}
+		return vm.OpSendVal, false
+	}
+	for position, arg := range args {
+		switch arg := arg.(type) {
+		case *ast.SpreadExpression:
+			if err := c.Compile(arg.Value); err != nil {
+				return err
+			}
+			c.EmitWithLine(vm.OpSendUnpack, line, vm.TmpVarOperand(0), vm.UnusedOperand(), vm.UnusedOperand())
+
+		case *ast.NamedArgument:
+			name := vm.ConstOperand(uint32(c.AddConstant(arg.Name)))
+			if variable, ok := referenceableVariable(arg.Value); ok {
+				c.EmitWithLine(vm.OpSendVarEx, line, c.variableOperand(variable), name, vm.UnusedOperand())
+				continue
+			}
+			if err := c.Compile(arg.Value); err != nil {
+				return err
+			}
+			c.EmitWithLine(vm.OpSendVal, line, vm.TmpVarOperand(0), name, vm.UnusedOperand())
+
+		default:
+			if variable, ok := referenceableVariable(arg); ok {
+				if send, ok := sendVariable(position); ok {
+					c.EmitWithLine(send, line, c.variableOperand(variable), vm.UnusedOperand(), vm.UnusedOperand())
+					continue
+				}
+			}
+			if index, ok := arg.(*ast.IndexExpression); ok && byRef != nil && byRef(position) {
+				element, ok, err := c.compileElementReference(index)
+				if err != nil {
+					return err
+				}
+				if ok {
+					c.EmitWithLine(vm.OpSendRef, line, element, vm.UnusedOperand(), vm.UnusedOperand())
+					continue
+				}
+			}
+			if err := c.Compile(arg); err != nil {
+				return err
+			}
+			c.EmitWithLine(vm.OpSendVal, line, vm.TmpVarOperand(0), vm.UnusedOperand(), vm.UnusedOperand())
+		}
+	}
+	return nil
+}
+
+// referenceableVariable returns the variable an argument consists of, if
+// it can be bound to a reference ($this and $GLOBALS cannot)
+func referenceableVariable(arg ast.Expr) (*ast.Variable, bool) {
+	variable, ok := arg.(*ast.Variable)
+	if !ok || variable.Name == "this" || variable.Name == "GLOBALS" {
+		return nil, false
+	}
+	return variable, true
+}
+
+// compileElementReference fetches an element of an array variable, at any
+// depth ($a['k'], $a['k'][0]), as a reference into a new temporary. ok is
+// false for other expressions, which have no element to bind.
+func (c *Compiler) compileElementReference(index *ast.IndexExpression) (vm.Operand, bool, error) {
+	if index.Index == nil {
+		return vm.Operand{}, false, nil
+	}
+	var container vm.Operand
+	switch left := index.Left.(type) {
+	case *ast.Variable:
+		variable, ok := referenceableVariable(left)
+		if !ok {
+			return vm.Operand{}, false, nil
+		}
+		container = c.variableOperand(variable)
+	case *ast.IndexExpression:
+		operand, ok, err := c.compileElementReference(left)
+		if err != nil || !ok {
+			return vm.Operand{}, ok, err
+		}
+		container = operand
+	default:
+		return vm.Operand{}, false, nil
+	}
+	key, err := c.compileOperand(index.Index)
+	if err != nil {
+		return vm.Operand{}, false, err
+	}
+	element := c.newTemp()
+	c.EmitWithLine(vm.OpFetchListW, uint32(index.Token.Pos.Line), container, key, element)
+	return element, true, nil
+}
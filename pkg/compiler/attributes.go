@@ -0,0 +1,112 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ========================================
+// Attributes
+// ========================================
+
+// compileAttributes converts attribute groups into attribute metadata.
+// Attribute names are resolved like class names; arguments must be
+// constant expressions, and are evaluated at compile time.
+func (c *Compiler) compileAttributes(groups []*ast.AttributeGroup) ([]*types.Attribute, error) {
+	var attrs []*types.Attribute
+	for _, group := range groups {
+		for _, node := range group.Attributes {
+			name := c.namespace.ResolveClassName(node.Name.Value)
+			if isSpecialClassName(name) {
+				return nil, fmt.Errorf("cannot use '%s' as attribute name as it is reserved", name)
+			}
+
+			if err := checkArguments(node.Arguments, nil, false); err != nil {
+				return nil, err
+			}
+
+			attr := &types.Attribute{Name: name}
+			for _, arg := range node.Arguments {
+				argument := types.AttributeArgument{}
+				switch arg := arg.(type) {
+				case *ast.SpreadExpression:
+					return nil, fmt.Errorf("cannot use unpacking in attribute argument list")
+				case *ast.NamedArgument:
+					argument.Name = arg.Name
+				}
+
+				value := argumentValue(arg)
+				if folded, ok := c.attributeArgumentValue(value); ok {
+					argument.Value = folded
+				} else if expr, ok := c.attributeConstantExpr(value); ok {
+					argument.Expr = expr
+				} else {
+					return nil, fmt.Errorf("constant expression contains invalid operations")
+				}
+				attr.Arguments = append(attr.Arguments, argument)
+			}
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs, nil
+}
+
+// attributeArgumentValue evaluates an attribute argument known at compile
+// time: a constant expression, a constant array or a Foo::class name
+func (c *Compiler) attributeArgumentValue(expr ast.Expr) (*types.Value, bool) {
+	if value, ok := constantExpressionValue(expr); ok {
+		return constantToValue(value), true
+	}
+	if arr, ok := constantArrayValue(expr); ok {
+		return types.NewArray(arr), true
+	}
+
+	if node, ok := expr.(*ast.StaticPropertyExpression); ok {
+		ident, isIdent := node.Class.(*ast.Identifier)
+		prop, isName := node.Property.(*ast.Identifier)
+		if isIdent && isName && strings.EqualFold(prop.Value, "class") && !isSpecialClassName(ident.Value) {
+			return types.NewString(c.namespace.ResolveClassName(ident.Value)), true
+		}
+	}
+	return nil, false
+}
+
+// attributeConstantExpr converts an attribute argument referring to class
+// constants (Attribute::TARGET_METHOD | Attribute::TARGET_FUNCTION) into a
+// constant expression evaluated at run time. self:: and static:: are kept
+// as is, to be resolved against the class declaring the attribute.
+func (c *Compiler) attributeConstantExpr(expr ast.Expr) (*types.ConstantExpr, bool) {
+	if value, ok := c.attributeArgumentValue(expr); ok {
+		return &types.ConstantExpr{Value: value}, true
+	}
+
+	switch node := expr.(type) {
+	case *ast.GroupedExpression:
+		return c.attributeConstantExpr(node.Expr)
+	case *ast.InfixExpression:
+		left, ok := c.attributeConstantExpr(node.Left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := c.attributeConstantExpr(node.Right)
+		if !ok {
+			return nil, false
+		}
+		return &types.ConstantExpr{Operator: node.Operator, Left: left, Right: right}, true
+	case *ast.StaticPropertyExpression:
+		class, isIdent := node.Class.(*ast.Identifier)
+		name, isName := node.Property.(*ast.Identifier)
+		if !isIdent || !isName {
+			return nil, false
+		}
+		className := class.Value
+		if !isSpecialClassName(className) {
+			className = c.namespace.ResolveClassName(className)
+		}
+		return &types.ConstantExpr{Class: className, Constant: name.Value}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,575 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// Class Declarations
+// ========================================
+
+// compileClassDeclaration compiles a class into a DECLARE_CLASS instruction
+// whose ClassDecl constant carries the members of the class
+func (c *Compiler) compileClassDeclaration(node *ast.ClassDeclaration) error {
+	if err := checkReadonlyProperties(node); err != nil {
+		return err
+	}
+
+	decl, err := c.newClassDecl(node.Name.Value, node.Attributes, node.DocComment)
+	if err != nil {
+		return err
+	}
+	for _, modifier := range node.Modifiers {
+		switch modifier {
+		case "abstract":
+			decl.Class.IsAbstract = true
+		case "final":
+			decl.Class.IsFinal = true
+		case "readonly":
+			decl.Class.IsReadOnly = true
+		}
+	}
+	outer := c.enterClass(decl.Class.Name, false)
+	defer func() { c.scope = outer }()
+
+	if node.Extends != nil {
+		decl.Parent = c.namespace.ResolveClassName(node.Extends.Value)
+		c.AddConstant(decl.Parent)
+	}
+	for _, iface := range node.Implements {
+		decl.Interfaces = append(decl.Interfaces, c.namespace.ResolveClassName(iface.Value))
+		c.AddConstant(decl.Interfaces[len(decl.Interfaces)-1])
+	}
+
+	init, err := c.compileClassBody(decl, node.Body)
+	if err != nil {
+		return err
+	}
+	c.emitDeclareClass(decl, uint32(node.Token.Pos.Line))
+	return c.compileInitializers(decl, init)
+}
+
+// compileTraitDeclaration compiles a trait. Traits are declared like
+// classes and applied to the classes using them when those are declared.
+func (c *Compiler) compileTraitDeclaration(node *ast.TraitDeclaration) error {
+	decl, err := c.newClassDecl(node.Name.Value, node.Attributes, node.DocComment)
+	if err != nil {
+		return err
+	}
+	decl.Class.IsTrait = true
+	outer := c.enterClass(decl.Class.Name, true)
+	defer func() { c.scope = outer }()
+
+	init, err := c.compileClassBody(decl, node.Body)
+	if err != nil {
+		return err
+	}
+	c.emitDeclareClass(decl, uint32(node.Token.Pos.Line))
+	return c.compileInitializers(decl, init)
+}
+
+// compileInterfaceDeclaration compiles an interface: its constants, and
+// its methods as abstract signatures
+func (c *Compiler) compileInterfaceDeclaration(node *ast.InterfaceDeclaration) error {
+	decl, err := c.newClassDecl(node.Name.Value, node.Attributes, node.DocComment)
+	if err != nil {
+		return err
+	}
+	decl.Class.IsInterface = true
+	outer := c.enterClass(decl.Class.Name, false)
+	defer func() { c.scope = outer }()
+	for _, parent := range node.Extends {
+		decl.Interfaces = append(decl.Interfaces, c.namespace.ResolveClassName(parent.Value))
+		c.AddConstant(decl.Interfaces[len(decl.Interfaces)-1])
+	}
+
+	init := &classInitializers{}
+	for _, member := range node.Constants {
+		if err := c.compileClassConstants(decl.Class, member, init); err != nil {
+			return err
+		}
+	}
+
+	for _, sig := range node.Body {
+		c.AddConstant(sig.Name.Value)
+		params, err := c.parameterDefs(sig.Parameters)
+		if err != nil {
+			return err
+		}
+		attrs, err := c.compileAttributes(sig.Attributes)
+		if err != nil {
+			return err
+		}
+		method := &types.MethodDef{
+			Name:           sig.Name.Value,
+			Visibility:     types.VisibilityPublic,
+			IsAbstract:     true,
+			NumParams:      len(sig.Parameters),
+			Parameters:     params,
+			ReturnByRef:    sig.ByRef,
+			DeclaringClass: decl.Class.Name,
+			Attributes:     attrs,
+			DocComment:     sig.DocComment,
+			StrictTypes:    c.strictTypes,
+		}
+		if sig.ReturnType != nil {
+			method.ReturnType = sig.ReturnType.String()
+		}
+		if err := addMethod(decl.Class, method); err != nil {
+			return err
+		}
+	}
+
+	c.emitDeclareClass(decl, uint32(node.Token.Pos.Line))
+	return c.compileInitializers(decl, init)
+}
+
+// newClassDecl starts the declaration of a class named in the current
+// namespace
+func (c *Compiler) newClassDecl(name string, attributes []*ast.AttributeGroup, docComment string) (*vm.ClassDecl, error) {
+	name = types.InternString(c.namespace.prefix(name))
+	c.AddConstant(name)
+
+	attrs, err := c.compileAttributes(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	class := types.NewClassEntry(name)
+	class.ShortName = lastSegment(name)
+	class.Namespace = c.namespace.Name()
+	class.Attributes = attrs
+	class.DocComment = docComment
+	return &vm.ClassDecl{Class: class}, nil
+}
+
+// emitDeclareClass emits the DECLARE_CLASS instruction of a declaration
+func (c *Compiler) emitDeclareClass(decl *vm.ClassDecl, line uint32) {
+	c.EmitWithLine(vm.OpDeclareClass, line,
+		vm.ConstOperand(uint32(c.AddConstant(decl))),
+		vm.UnusedOperand(),
+		vm.UnusedOperand())
+}
+
+// classInitializers are the constants and static properties of a class
+// whose initial values are not constant expressions, evaluated once the
+// class is declared
+type classInitializers struct {
+	constants []*ast.ConstantItem
+	statics   []*ast.PropertyItem
+}
+
+// compileClassConstants adds the constants of a declaration to a class,
+// deferring those whose value is not a constant expression
+func (c *Compiler) compileClassConstants(class *types.ClassEntry, member *ast.ClassConstantDeclaration, deferred *classInitializers) error {
+	attrs, err := c.compileAttributes(member.Attributes)
+	if err != nil {
+		return err
+	}
+	for _, item := range member.Constants {
+		if _, exists := class.Constants[item.Name.Value]; exists {
+			return fmt.Errorf("cannot redefine class constant %s::%s", class.Name, item.Name.Value)
+		}
+		value, ok := constantExpressionValue(item.Value)
+		if !ok {
+			if value, ok = constantArrayValue(item.Value); !ok {
+				deferred.constants = append(deferred.constants, item)
+			}
+		}
+		class.Constants[item.Name.Value] = &types.ClassConstant{
+			Name:       item.Name.Value,
+			Value:      constantToValue(value),
+			Visibility: visibility(member.Visibility),
+			Attributes: attrs,
+			DocComment: member.DocComment,
+		}
+	}
+	return nil
+}
+
+// compileClassBody adds the constants, properties, methods and trait uses
+// of a class body to its declaration. Method bodies are compiled into op
+// arrays of their own.
+// The constants and static properties whose initial value is not a
+// constant expression are returned, to be initialized once the class is
+// declared.
+func (c *Compiler) compileClassBody(decl *vm.ClassDecl, body []ast.Stmt) (*classInitializers, error) {
+	class := decl.Class
+	deferred := &classInitializers{}
+
+	for _, stmt := range body {
+		switch member := stmt.(type) {
+		case *ast.ClassConstantDeclaration:
+			if err := c.compileClassConstants(class, member, deferred); err != nil {
+				return nil, err
+			}
+
+		case *ast.PropertyDeclaration:
+			attrs, err := c.compileAttributes(member.Attributes)
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range member.Properties {
+				name := types.InternString(item.Name.Name)
+				if _, exists := class.Properties[name]; exists {
+					return nil, fmt.Errorf("cannot redeclare %s::$%s", class.Name, name)
+				}
+				c.AddConstant(name)
+
+				prop := &types.PropertyDef{
+					Name:           name,
+					Visibility:     visibility(member.Visibility),
+					IsStatic:       member.Static,
+					IsReadOnly:     member.Readonly,
+					DeclaringClass: class.Name,
+					Attributes:     attrs,
+					DocComment:     member.DocComment,
+				}
+				if member.Type != nil {
+					prop.Type = member.Type.String()
+				}
+				if item.DefaultValue != nil {
+					prop.HasDefault = true
+					value, ok := constantExpressionValue(item.DefaultValue)
+					if !ok {
+						value, ok = constantArrayValue(item.DefaultValue)
+					}
+					if ok {
+						prop.Default = constantToValue(value)
+					} else if member.Static {
+						deferred.statics = append(deferred.statics, item)
+					}
+				} else if member.Type == nil {
+					// Untyped properties default to null
+					prop.Default = types.NewNull()
+				}
+				class.Properties[name] = prop
+				class.PropertyOrder = append(class.PropertyOrder, name)
+
+				if member.Static {
+					class.StaticProperties[name] = types.NewNull()
+					if prop.Default != nil {
+						class.StaticProperties[name] = prop.Default
+					}
+				} else if prop.Default != nil {
+					class.DefaultProperties[name] = prop.Default
+				}
+			}
+
+		case *ast.MethodDeclaration:
+			if err := c.compileMethod(decl, member); err != nil {
+				return nil, err
+			}
+
+		case *ast.TraitUse:
+			if err := c.compileTraitUse(decl, member); err != nil {
+				return nil, err
+			}
+
+		default:
+			if err := c.Compile(stmt); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return deferred, nil
+}
+
+// compileMethod adds a method to a declaration and compiles its body like
+// a function body, with $this defined for instance methods
+func (c *Compiler) compileMethod(decl *vm.ClassDecl, node *ast.MethodDeclaration) error {
+	name := types.InternString(node.Name.Value)
+	c.AddConstant(name)
+
+	params, err := c.parameterDefs(node.Parameters)
+	if err != nil {
+		return err
+	}
+	attrs, err := c.compileAttributes(node.Attributes)
+	if err != nil {
+		return err
+	}
+
+	lower := strings.ToLower(name)
+	method := &types.MethodDef{
+		Name:           name,
+		Visibility:     visibility(node.Visibility),
+		IsStatic:       node.Static,
+		IsFinal:        node.Final,
+		IsAbstract:     node.Abstract || node.Body == nil,
+		NumParams:      len(node.Parameters),
+		Parameters:     params,
+		ReturnByRef:    node.ByRef,
+		IsConstructor:  lower == "__construct",
+		IsDestructor:   lower == "__destruct",
+		IsMagic:        strings.HasPrefix(name, "__"),
+		DeclaringClass: decl.Class.Name,
+		Attributes:     attrs,
+		DocComment:     node.DocComment,
+		StrictTypes:    c.strictTypes,
+	}
+	if node.ReturnType != nil {
+		method.ReturnType = node.ReturnType.String()
+	}
+	if method.IsAbstract && node.Body != nil {
+		return fmt.Errorf("abstract function %s::%s() cannot contain body", decl.Class.Name, name)
+	}
+	if err := addMethod(decl.Class, method); err != nil {
+		return err
+	}
+	if method.IsAbstract {
+		return nil
+	}
+
+	outerOpArray := c.enterOpArray()
+	c.EnterScope()
+	c.enterFunction(node.ReturnType)
+	outer := c.enterFunctionScope(name)
+
+	line := uint32(node.Token.Pos.Line)
+	for i, param := range node.Parameters {
+		symbol := c.DefineVariable(param.Name.Name)
+
+		if param.Variadic {
+			c.EmitWithLine(vm.OpRecvVariadic, line,
+				vm.ConstOperand(uint32(i)),
+				vm.UnusedOperand(),
+				vm.CVOperand(uint32(symbol.Index)))
+		} else if param.DefaultValue != nil {
+			if err := c.Compile(param.DefaultValue); err != nil {
+				return err
+			}
+			c.EmitWithLine(vm.OpRecvInit, line,
+				vm.ConstOperand(uint32(i)),
+				vm.TmpVarOperand(0),
+				vm.CVOperand(uint32(symbol.Index)))
+		} else {
+			c.EmitWithLine(vm.OpRecv, line,
+				vm.ConstOperand(uint32(i)),
+				vm.UnusedOperand(),
+				vm.CVOperand(uint32(symbol.Index)))
+		}
+	}
+
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+
+	// Add implicit return if method doesn't end with return
+	c.emitImplicitReturn(line)
+
+	method.NumLocals = c.symbolTable.NumDefinitions()
+	method.Variables = c.symbolTable.VariableNames()
+	c.exitFunction()
+	c.ExitScope()
+	c.scope = outer
+
+	// The function table lists the method as Class::method
+	fn := &vm.CompiledFunction{
+		Name:        decl.Class.Name + "::" + name,
+		NumLocals:   method.NumLocals,
+		NumParams:   method.NumParams,
+		Variables:   method.Variables,
+		Parameters:  params,
+		Attributes:  attrs,
+		ReturnType:  method.ReturnType,
+		ReturnByRef: method.ReturnByRef,
+		DocComment:  method.DocComment,
+		StrictTypes: method.StrictTypes,
+	}
+	c.exitOpArray(outerOpArray, fn)
+	method.Instructions = make([]interface{}, len(fn.Instructions))
+	for i, instr := range fn.Instructions {
+		method.Instructions[i] = instr
+	}
+	method.Constants = fn.Constants
+	method.TryCatch = fn.TryCatch
+	method.File = fn.File
+	return nil
+}
+
+// compileTraitUse records the traits a use block names and its conflict
+// resolution rules: "T::m insteadof U" selects T's m, and "[T::]m as
+// [visibility] [alias]" becomes an alias "T::m[:visibility]" keyed by the
+// alias, or by m for a visibility change. Unqualified aliases leave the
+// trait name empty, to be resolved against the used traits.
+func (c *Compiler) compileTraitUse(decl *vm.ClassDecl, node *ast.TraitUse) error {
+	class := decl.Class
+	for _, trait := range node.Traits {
+		name := c.namespace.ResolveClassName(trait.Value)
+		c.AddConstant(name)
+		decl.Traits = append(decl.Traits, name)
+	}
+
+	for _, adaptation := range node.Adaptations {
+		switch rule := adaptation.(type) {
+		case *ast.TraitPrecedence:
+			method := rule.MethodName.Value
+			trait := c.namespace.ResolveClassName(rule.TraitName.Value)
+			for _, excluded := range rule.Instead {
+				if strings.EqualFold(c.namespace.ResolveClassName(excluded.Value), trait) {
+					return fmt.Errorf("inconsistent insteadof definition: the method %s is to be used from %s, but %s is also on the exclude list",
+						method, trait, trait)
+				}
+			}
+			class.TraitPrecedence[method] = trait
+
+		case *ast.TraitAlias:
+			method := rule.MethodName.Value
+			trait := ""
+			if rule.TraitName != nil {
+				trait = c.namespace.ResolveClassName(rule.TraitName.Value)
+			}
+
+			spec := trait + "::" + method
+			if rule.Visibility != "" {
+				spec += ":" + rule.Visibility
+			}
+			alias := method
+			if rule.Alias != nil {
+				alias = rule.Alias.Value
+			}
+			class.TraitAliases[alias] = spec
+		}
+	}
+	return nil
+}
+
+// compileInitializers evaluates the constants and static properties whose
+// initial value is not a constant expression, once their class is
+// declared. Constants are initialized first, as static properties may
+// refer to them.
+func (c *Compiler) compileInitializers(decl *vm.ClassDecl, init *classInitializers) error {
+	outer := c.initializer
+	c.initializer = decl
+	defer func() { c.initializer = outer }()
+
+	class := vm.ConstOperand(uint32(c.AddConstant(decl.Class.Name)))
+	for _, item := range init.constants {
+		if err := c.Compile(item.Value); err != nil {
+			return err
+		}
+		c.EmitWithExtended(vm.OpDeclareConst, uint32(item.Name.Token.Pos.Line),
+			vm.DeclareConstClass,
+			class,
+			vm.ConstOperand(uint32(c.AddConstant(item.Name.Value))),
+			vm.TmpVarOperand(0))
+	}
+	for _, item := range init.statics {
+		if err := c.Compile(item.DefaultValue); err != nil {
+			return err
+		}
+		c.EmitWithExtended(vm.OpAssignStaticProp, uint32(item.Name.Token.Pos.Line),
+			vm.StaticPropInit,
+			class,
+			vm.ConstOperand(uint32(c.AddConstant(item.Name.Name))),
+			vm.TmpVarOperand(0))
+	}
+	return nil
+}
+
+// addMethod adds a method to a class, rejecting redeclarations
+func addMethod(class *types.ClassEntry, method *types.MethodDef) error {
+	for name := range class.Methods {
+		if strings.EqualFold(name, method.Name) {
+			return fmt.Errorf("cannot redeclare %s::%s()", class.Name, method.Name)
+		}
+	}
+	class.Methods[method.Name] = method
+	return nil
+}
+
+// parameterDefs returns the parameter definitions of a signature
+func (c *Compiler) parameterDefs(params []*ast.Parameter) ([]*types.ParameterDef, error) {
+	defs := make([]*types.ParameterDef, len(params))
+	for i, param := range params {
+		attrs, err := c.compileAttributes(param.Attributes)
+		if err != nil {
+			return nil, err
+		}
+
+		def := &types.ParameterDef{
+			Name:        param.Name.Name,
+			IsVariadic:  param.Variadic,
+			PassedByRef: param.ByRef,
+			HasDefault:  param.DefaultValue != nil,
+			Attributes:  attrs,
+		}
+		if param.Type != nil {
+			def.Type = param.Type.String()
+		}
+		if def.HasDefault {
+			if value, ok := constantExpressionValue(param.DefaultValue); ok {
+				def.Default = constantToValue(value)
+			}
+		}
+		defs[i] = def
+	}
+	return defs, nil
+}
+
+// visibility converts a visibility modifier, public by default
+func visibility(modifier string) types.PropertyVisibility {
+	switch strings.ToLower(modifier) {
+	case "protected":
+		return types.VisibilityProtected
+	case "private":
+		return types.VisibilityPrivate
+	}
+	return types.VisibilityPublic
+}
+
+// constantArrayValue evaluates an array literal made of constant keys and
+// values
+func constantArrayValue(expr ast.Expr) (*types.Array, bool) {
+	node, ok := expr.(*ast.ArrayExpression)
+	if !ok {
+		return nil, false
+	}
+
+	arr := types.NewEmptyArray()
+	for _, elem := range node.Elements {
+		if elem.Value == nil || elem.ByRef {
+			return nil, false
+		}
+		value, ok := constantExpressionValue(elem.Value)
+		if !ok {
+			if value, ok = constantArrayValue(elem.Value); !ok {
+				return nil, false
+			}
+		}
+		if elem.Key == nil {
+			arr.Append(constantToValue(value))
+			continue
+		}
+		key, ok := constantExpressionValue(elem.Key)
+		if !ok {
+			return nil, false
+		}
+		arr.Set(constantToValue(key), constantToValue(value))
+	}
+	return arr, true
+}
+
+// constantToValue converts a folded constant to a value. Constants that
+// cannot be folded are null.
+func constantToValue(value interface{}) *types.Value {
+	switch v := value.(type) {
+	case int64:
+		return types.NewInt(v)
+	case float64:
+		return types.NewFloat(v)
+	case string:
+		return types.NewString(v)
+	case bool:
+		return types.NewBool(v)
+	case *types.Array:
+		return types.NewArray(v)
+	}
+	return types.NewNull()
+}
@@ -0,0 +1,290 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// Null Coalescing and Nullsafe Chains
+// ========================================
+
+// compileCoalesce compiles $a ?? $b. The left side is fetched as by
+// isset(), so undefined variables, keys and properties raise no notice;
+// COALESCE skips the right side when the left one is not null. The value
+// is left in temp 0.
+func (c *Compiler) compileCoalesce(node *ast.InfixExpression) error {
+	var jumps []int
+	if err := c.compileChain(node.Left, true, &jumps); err != nil {
+		return err
+	}
+	c.patchChainJumps(jumps, 0)
+
+	coalesce := c.EmitWithLine(vm.OpCoalesce, uint32(node.Token.Pos.Line),
+		vm.TmpVarOperand(0),
+		vm.UnusedOperand(),
+		vm.TmpVarOperand(0))
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+	return c.ChangeOperand(coalesce, 2, vm.ConstOperand(uint32(c.CurrentPosition())))
+}
+
+// compileCoalesceAssignment compiles $a ??= $b: the right side is only
+// evaluated, and the left side only assigned, when the left side is null
+// or unset. Keys and property names are computed once. The value is left
+// in temp 0.
+func (c *Compiler) compileCoalesceAssignment(node *ast.AssignmentExpression) error {
+	line := uint32(node.Token.Pos.Line)
+
+	var assign func()
+	switch left := node.Left.(type) {
+	case *ast.Variable:
+		target := c.variableOperand(left)
+		c.EmitWithLine(vm.OpFetchIs, line, target, vm.UnusedOperand(), vm.TmpVarOperand(0))
+		assign = func() {
+			c.EmitWithLine(vm.OpAssign, line, vm.UnusedOperand(), vm.TmpVarOperand(0), target)
+		}
+
+	case *ast.IndexExpression:
+		container, ok := left.Left.(*ast.Variable)
+		if !ok || left.Index == nil {
+			return fmt.Errorf("??= on %s is not supported", left.String())
+		}
+		key, err := c.compileOperand(left.Index)
+		if err != nil {
+			return err
+		}
+		array := c.variableOperand(container)
+		c.EmitWithLine(vm.OpFetchDimIs, line, array, key, vm.TmpVarOperand(0))
+		assign = func() {
+			c.EmitWithLine(vm.OpAssignDim, line, array, key, vm.TmpVarOperand(0))
+		}
+
+	case *ast.PropertyExpression:
+		if hasNullsafe(left) {
+			return fmt.Errorf("cannot use nullsafe operator in write context")
+		}
+		object, property, err := c.compilePropertyTarget(left)
+		if err != nil {
+			return err
+		}
+		c.EmitWithLine(vm.OpFetchObjIs, line, object, property, vm.TmpVarOperand(0))
+		assign = func() {
+			c.EmitWithLine(vm.OpAssignObj, line, object, property, vm.TmpVarOperand(0))
+		}
+
+	case *ast.NullsafePropertyExpression:
+		return fmt.Errorf("cannot use nullsafe operator in write context")
+
+	default:
+		return fmt.Errorf("??= on %s is not supported", node.Left.String())
+	}
+
+	coalesce := c.EmitWithLine(vm.OpCoalesce, line, vm.TmpVarOperand(0), vm.UnusedOperand(), vm.TmpVarOperand(0))
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+	assign()
+	return c.ChangeOperand(coalesce, 2, vm.ConstOperand(uint32(c.CurrentPosition())))
+}
+
+// compileNullsafeChain compiles a member chain, leaving its value in temp
+// 0. A null object before any ?-> makes the whole chain null.
+func (c *Compiler) compileNullsafeChain(node ast.Expr) error {
+	var jumps []int
+	if err := c.compileChain(node, false, &jumps); err != nil {
+		return err
+	}
+	c.patchChainJumps(jumps, 0)
+	return nil
+}
+
+// compileChain compiles a chain of element, property and method accesses
+// ($a[0]->b?->c()), leaving its value in temp 0. With quiet set, variables,
+// elements and properties are fetched as by isset(). Each ?-> emits a
+// JMP_NULL, whose position is added to jumps for the caller to patch to
+// the end of the chain.
+func (c *Compiler) compileChain(node ast.Expr, quiet bool, jumps *[]int) error {
+	switch node := node.(type) {
+	case *ast.Variable:
+		if !quiet || node.Name == "GLOBALS" || node.Name == "this" {
+			return c.Compile(node)
+		}
+		c.EmitWithLine(vm.OpFetchIs, uint32(node.Token.Pos.Line),
+			c.variableOperand(node),
+			vm.UnusedOperand(),
+			vm.TmpVarOperand(0))
+		return nil
+
+	case *ast.VariableVariable:
+		if !quiet {
+			return c.Compile(node)
+		}
+		if err := c.Compile(node.Name); err != nil {
+			return err
+		}
+		c.EmitWithExtended(vm.OpFetchIs, uint32(node.Token.Pos.Line), vm.FetchByName,
+			vm.TmpVarOperand(0),
+			vm.UnusedOperand(),
+			vm.TmpVarOperand(0))
+		return nil
+
+	case *ast.IndexExpression:
+		if node.Index == nil {
+			return c.Compile(node)
+		}
+		container, err := c.compileChainContainer(node.Left, quiet, jumps)
+		if err != nil {
+			return err
+		}
+		fetch := vm.OpFetchDimR
+		if quiet {
+			fetch = vm.OpFetchDimIs
+		}
+		return c.compileChainLink(container, node.Index, fetch, uint32(node.Token.Pos.Line))
+
+	case *ast.PropertyExpression:
+		container, err := c.compileChainContainer(node.Object, quiet, jumps)
+		if err != nil {
+			return err
+		}
+		fetch := vm.OpFetchObjR
+		if quiet {
+			fetch = vm.OpFetchObjIs
+		}
+		return c.compileChainLink(container, node.Property, fetch, uint32(node.Token.Pos.Line))
+
+	case *ast.NullsafePropertyExpression:
+		if err := c.compileChain(node.Object, quiet, jumps); err != nil {
+			return err
+		}
+		c.emitJmpNull(jumps, uint32(node.Token.Pos.Line))
+		fetch := vm.OpFetchObjR
+		if quiet {
+			fetch = vm.OpFetchObjIs
+		}
+		return c.compileChainLink(vm.TmpVarOperand(0), node.Property, fetch, uint32(node.Token.Pos.Line))
+
+	case *ast.MethodCallExpression:
+		return c.compileChainMethodCall(node, jumps)
+	}
+
+	return c.Compile(node)
+}
+
+// compileChainContainer compiles the container of an element or property
+// in a chain, returning its operand: a variable is used as the compiled
+// variable, as by containerOperand
+func (c *Compiler) compileChainContainer(node ast.Expr, quiet bool, jumps *[]int) (vm.Operand, error) {
+	if variable, ok := node.(*ast.Variable); ok && variable.Name != "this" && variable.Name != "GLOBALS" {
+		return c.variableOperand(variable), nil
+	}
+	return vm.TmpVarOperand(0), c.compileChain(node, quiet, jumps)
+}
+
+// compileChainLink fetches an element or property of a container into
+// temp 0
+func (c *Compiler) compileChainLink(container vm.Operand, member ast.Expr, fetch vm.Opcode, line uint32) error {
+	property := fetch == vm.OpFetchObjR || fetch == vm.OpFetchObjIs
+	container, key, err := c.compileMember(container, member, property, line)
+	if err != nil {
+		return err
+	}
+	c.EmitWithLine(fetch, line, container, key, vm.TmpVarOperand(0))
+	return nil
+}
+
+// compileMember returns the operands accessing an element (or, with
+// property set, a property) of a container. Literal keys and property
+// names become constants; other keys are computed into temp 0, with a
+// container held in temp 0 moved to a temporary of its own first.
+func (c *Compiler) compileMember(container vm.Operand, member ast.Expr, property bool, line uint32) (vm.Operand, vm.Operand, error) {
+	if name, ok := member.(*ast.Identifier); ok && property {
+		return container, vm.ConstOperand(uint32(c.AddConstant(name.Value))), nil
+	}
+	if key, ok := getConstantValue(member); ok {
+		return container, vm.ConstOperand(uint32(c.AddConstant(key))), nil
+	}
+
+	if container == vm.TmpVarOperand(0) {
+		container = c.keepValue(line)
+	}
+	err := c.Compile(member)
+	return container, vm.TmpVarOperand(0), err
+}
+
+// compileChainMethodCall compiles a method call within a member chain. The
+// object is always fetched for reading: calling a method on an undefined
+// variable is an error even on the left of ??.
+func (c *Compiler) compileChainMethodCall(node *ast.MethodCallExpression, jumps *[]int) error {
+	line := uint32(node.Token.Pos.Line)
+
+	if node.Nullsafe && ast.IsFirstClassCallable(node.Arguments) {
+		return fmt.Errorf("cannot combine nullsafe operator with Closure creation")
+	}
+	if err := checkArguments(node.Arguments, nil, false); err != nil {
+		return err
+	}
+
+	if err := c.compileChain(node.Object, false, jumps); err != nil {
+		return err
+	}
+	if node.Nullsafe {
+		c.emitJmpNull(jumps, line)
+	}
+
+	object, method, err := c.compileMember(vm.TmpVarOperand(0), node.Method, true, line)
+	if err != nil {
+		return err
+	}
+	c.EmitWithLine(vm.OpInitMethodCall, line, object, method, vm.UnusedOperand())
+	if ast.IsFirstClassCallable(node.Arguments) {
+		c.EmitWithLine(vm.OpCallableConvert, line, vm.UnusedOperand(), vm.UnusedOperand(), vm.TmpVarOperand(0))
+		return nil
+	}
+	if err := c.compileArguments(node.Arguments, line, nil); err != nil {
+		return err
+	}
+	c.EmitWithExtended(vm.OpDoFcall, line, uint32(len(node.Arguments)),
+		vm.UnusedOperand(),
+		vm.UnusedOperand(),
+		vm.TmpVarOperand(0))
+	return nil
+}
+
+// emitJmpNull emits the JMP_NULL of a ?-> on the object in temp 0
+func (c *Compiler) emitJmpNull(jumps *[]int, line uint32) {
+	*jumps = append(*jumps, c.EmitWithLine(vm.OpJmpNull, line,
+		vm.TmpVarOperand(0),
+		vm.UnusedOperand(),
+		vm.TmpVarOperand(0)))
+}
+
+// patchChainJumps points the JMP_NULLs of a chain at the next instruction,
+// with the extended value selecting the result of a short-circuited chain
+// (vm.JmpNullIsset, vm.JmpNullEmpty)
+func (c *Compiler) patchChainJumps(jumps []int, extended uint32) {
+	end := vm.ConstOperand(uint32(c.CurrentPosition()))
+	for _, pos := range jumps {
+		c.ChangeOperand(pos, 2, end)
+		c.instructions[pos].ExtendedValue = extended
+	}
+}
+
+// hasNullsafe reports whether a member chain contains ?->
+func hasNullsafe(node ast.Expr) bool {
+	switch node := node.(type) {
+	case *ast.NullsafePropertyExpression:
+		return true
+	case *ast.PropertyExpression:
+		return hasNullsafe(node.Object)
+	case *ast.MethodCallExpression:
+		return node.Nullsafe || hasNullsafe(node.Object)
+	case *ast.IndexExpression:
+		return hasNullsafe(node.Left)
+	}
+	return false
+}
@@ -1,9 +1,16 @@
 package compiler
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
 	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/lexer"
+	"github.com/krizos/php-go/pkg/parser"
+	"github.com/krizos/php-go/pkg/types"
 	"github.com/krizos/php-go/pkg/vm"
 )
 
@@ -29,6 +36,56 @@ type Compiler struct {
 
 	// loopStack tracks nested loops for break/continue
 	loopStack []*LoopContext
+
+	// namespace tracks the current namespace and its imports for name resolution
+	namespace *NamespaceContext
+
+	// temps is the number of temporaries newTemp allocated in the op array
+	// being emitted
+	temps int
+
+	// signatures maps the lowercased names of the functions declared in the
+	// code being compiled to their parameters, for checking named arguments
+	signatures map[string][]*ast.Parameter
+
+	// returnTypes holds the declared return types of the functions being
+	// compiled, innermost last ("" when undeclared)
+	returnTypes []string
+
+	// strictTypes is set by declare(strict_types=1)
+	strictTypes bool
+
+	// ticks is the N of the declare(ticks=N) in effect, 0 outside one
+	ticks int
+
+	// firstStatement is the top-level statement being compiled while
+	// only declare statements precede it
+	firstStatement ast.Stmt
+
+	// file is the path of the script, for __FILE__ and __DIR__
+	file string
+
+	// scope names the class and function being compiled, for the magic
+	// constants
+	scope magicScope
+
+	// initializer is the class whose deferred constant and static property
+	// initializers are being compiled
+	initializer *vm.ClassDecl
+
+	// functions holds the op arrays of the functions, methods and closures
+	// compiled so far, in declaration order
+	functions []*vm.CompiledFunction
+
+	// optimization selects the passes run over each finished op array
+	optimization OptimizationLevel
+
+	// tryCatch is the exception table of the op array being emitted
+	tryCatch []types.TryCatchElement
+
+	// tryStack tracks the try statements being compiled in the op array,
+	// innermost last
+	tryStack []*tryContext
 }
 
 // LoopContext tracks information about a loop for break/continue
@@ -41,6 +98,18 @@ type LoopContext struct {
 
 	// continueJumps holds positions of continue statements to be patched
 	continueJumps []int
+
+	// tryDepth is the number of try statements enclosing the loop, which
+	// break and continue do not leave
+	tryDepth int
+
+	// iterator is the iterator of a foreach loop, freed by the break and
+	// continue statements leaving it for an outer loop
+	iterator *vm.Operand
+
+	// isSwitch is set for switch statements, which continue treats like
+	// break
+	isSwitch bool
 }
 
 // EmittedInstruction tracks metadata about an emitted instruction
@@ -57,6 +126,7 @@ func New() *Compiler {
 		constantMap:         make(map[interface{}]int),
 		lastInstruction:     EmittedInstruction{},
 		previousInstruction: EmittedInstruction{},
+		namespace:           NewNamespaceContext(""),
 	}
 	c.InitSymbolTable()
 	return c
@@ -68,7 +138,12 @@ func New() *Compiler {
 
 // AddConstant adds a constant to the constant table
 // Returns the index of the constant (reuses existing if duplicate)
+// Strings are interned, so the op arrays of every file share them
 func (c *Compiler) AddConstant(value interface{}) int {
+	if s, ok := value.(string); ok {
+		value = types.InternString(s)
+	}
+
 	// Check if constant already exists
 	if idx, ok := c.constantMap[value]; ok {
 		return idx
@@ -231,22 +306,165 @@ func (c *Compiler) RemoveLastInstruction() {
 	c.lastInstruction = c.previousInstruction
 }
 
+// ========================================
+// Op Arrays
+// ========================================
+
+// opArrayState is the emission state of an op array. Each function,
+// method and closure body is compiled into an op array of its own, with
+// its own instructions and constant table, so jump targets and constant
+// indices are relative to the body.
+type opArrayState struct {
+	instructions        vm.Instructions
+	constants           []interface{}
+	constantMap         map[interface{}]int
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+	loopStack           []*LoopContext
+	tryCatch            []types.TryCatchElement
+	tryStack            []*tryContext
+	temps               int
+
+	// function is the slot of the function table reserved for the op array
+	// started, so op arrays are listed in the order they are declared
+	function int
+}
+
+// enterOpArray starts emitting into a new op array, returning the state of
+// the enclosing one
+func (c *Compiler) enterOpArray() opArrayState {
+	outer := opArrayState{
+		instructions:        c.instructions,
+		constants:           c.constants,
+		constantMap:         c.constantMap,
+		lastInstruction:     c.lastInstruction,
+		previousInstruction: c.previousInstruction,
+		loopStack:           c.loopStack,
+		tryCatch:            c.tryCatch,
+		tryStack:            c.tryStack,
+		temps:               c.temps,
+		function:            len(c.functions),
+	}
+	c.functions = append(c.functions, nil)
+	c.instructions = vm.Instructions{}
+	c.constants = []interface{}{}
+	c.constantMap = make(map[interface{}]int)
+	c.lastInstruction = EmittedInstruction{}
+	c.previousInstruction = EmittedInstruction{}
+	c.loopStack = nil
+	c.tryCatch = nil
+	c.tryStack = nil
+	c.temps = 0
+	return outer
+}
+
+// exitOpArray finishes the op array being emitted into fn, adds it to the
+// function table and resumes emitting into the enclosing one
+func (c *Compiler) exitOpArray(outer opArrayState, fn *vm.CompiledFunction) {
+	c.optimize(fn)
+	fn.NumLocals = max(len(fn.Variables), fn.NumParams) + c.temps + 1
+	fn.Instructions = c.instructions
+	fn.Constants = c.constants
+	fn.TryCatch = c.tryCatch
+	fn.File = c.file
+	c.functions[outer.function] = fn
+
+	c.instructions = outer.instructions
+	c.constants = outer.constants
+	c.constantMap = outer.constantMap
+	c.lastInstruction = outer.lastInstruction
+	c.previousInstruction = outer.previousInstruction
+	c.loopStack = outer.loopStack
+	c.tryCatch = outer.tryCatch
+	c.tryStack = outer.tryStack
+	c.temps = outer.temps
+}
+
+// newTemp allocates a temporary of the op array being emitted. Expressions
+// leave their value in temp 0, so a value that must survive the
+// compilation of other expressions is moved to a temporary of its own.
+// Temporaries are not reused; they follow the compiled variables in the
+// locals of a call (see vm.CompiledFunction).
+func (c *Compiler) newTemp() vm.Operand {
+	c.temps++
+	return vm.TmpVarOperand(uint32(c.temps))
+}
+
+// keepValue moves the value of the expression just compiled out of temp 0
+// into a new temporary, which it returns
+func (c *Compiler) keepValue(line uint32) vm.Operand {
+	temp := c.newTemp()
+	c.EmitWithLine(vm.OpQMAssign, line, vm.TmpVarOperand(0), vm.UnusedOperand(), temp)
+	return temp
+}
+
 // ========================================
 // Program Assembly
 // ========================================
 
-// Bytecode represents the compiled bytecode program
+// Bytecode represents the compiled bytecode program: the main op array and
+// the function table holding the op arrays of the functions, methods and
+// closures it declares
 type Bytecode struct {
 	Instructions vm.Instructions
 	Constants    []interface{}
+	Variables    []string               // Global variable names, indexed by CV number
+	StrictTypes  bool                   // Compiled with declare(strict_types=1)
+	Functions    []*vm.CompiledFunction // Declared op arrays, in declaration order
+	TryCatch     []types.TryCatchElement // Exception table of the main op array
 }
 
 // Bytecode assembles and returns the final compiled bytecode
 func (c *Compiler) Bytecode() *Bytecode {
+	c.optimize(nil)
 	return &Bytecode{
 		Instructions: c.instructions,
 		Constants:    c.constants,
+		Variables:    c.symbolTable.VariableNames(),
+		StrictTypes:  c.strictTypes,
+		Functions:    c.functions,
+		TryCatch:     c.tryCatch,
+	}
+}
+
+// CompileScript parses and compiles a PHP file into a script the VM can
+// execute or include. It matches the vm.ScriptCompiler signature.
+func CompileScript(path string, source []byte) (*vm.Script, error) {
+	return compileScript(path, source, OptimizeNone)
+}
+
+// ScriptCompiler returns a vm.ScriptCompiler compiling at an optimization
+// level
+func ScriptCompiler(level OptimizationLevel) vm.ScriptCompiler {
+	return func(path string, source []byte) (*vm.Script, error) {
+		return compileScript(path, source, level)
+	}
+}
+
+// compileScript parses and compiles a PHP file at an optimization level
+func compileScript(path string, source []byte, level OptimizationLevel) (*vm.Script, error) {
+	p := parser.New(lexer.New(string(source), path))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		return nil, fmt.Errorf("PHP Parse error: %s in %s", errs[0], path)
+	}
+
+	c := New()
+	c.SetFile(path)
+	c.SetOptimizationLevel(level)
+	if err := c.Compile(program); err != nil {
+		return nil, fmt.Errorf("PHP Compile error: %v in %s", err, path)
 	}
+
+	bytecode := c.Bytecode()
+	return &vm.Script{
+		Path:         path,
+		Instructions: bytecode.Instructions,
+		Constants:    bytecode.Constants,
+		Variables:    bytecode.Variables,
+		StrictTypes:  bytecode.StrictTypes,
+		TryCatch:     bytecode.TryCatch,
+	}, nil
 }
 
 // ========================================
@@ -257,11 +475,20 @@ func (c *Compiler) Bytecode() *Bytecode {
 func (c *Compiler) Compile(node ast.Node) error {
 	switch node := node.(type) {
 	case *ast.Program:
+		c.declareSignatures(node.Statements, c.namespace.Name())
+		leading := true
 		for _, stmt := range node.Statements {
-			if err := c.Compile(stmt); err != nil {
+			c.firstStatement = nil
+			if leading {
+				c.firstStatement = stmt
+			}
+			_, isDeclare := stmt.(*ast.DeclareStatement)
+			leading = leading && isDeclare
+			if err := c.compileStatement(stmt); err != nil {
 				return err
 			}
 		}
+		c.firstStatement = nil
 		return nil
 
 	// Statements
@@ -270,12 +497,12 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return err
 		}
 		// Pop the result since expression statements don't use their value
-		c.Emit(vm.OpFree, vm.TmpVarOperand(0)) // TODO: track temp var numbers properly
+		c.Emit(vm.OpFree, vm.TmpVarOperand(0))
 		return nil
 
 	case *ast.BlockStatement:
 		for i, stmt := range node.Statements {
-			if err := c.Compile(stmt); err != nil {
+			if err := c.compileStatement(stmt); err != nil {
 				return err
 			}
 
@@ -303,14 +530,48 @@ func (c *Compiler) Compile(node ast.Node) error {
 		return nil
 
 	case *ast.ReturnStatement:
-		if node.ReturnValue != nil {
-			if err := c.Compile(node.ReturnValue); err != nil {
+		return c.compileReturn(node)
+
+	// Static Variable Declaration
+	case *ast.StaticVarStatement:
+		line := uint32(node.Token.Pos.Line)
+		for _, staticVar := range node.Vars {
+			symbol := c.variableSymbol(staticVar.Name.Name)
+			target := vm.CVOperand(uint32(symbol.Index))
+			name := vm.ConstOperand(uint32(c.AddConstant(staticVar.Name.Name)))
+
+			if staticVar.Value == nil {
+				c.EmitWithLine(vm.OpBindStatic, line, name, vm.UnusedOperand(), target)
+				continue
+			}
+
+			// The initializer only runs until the variable is initialized;
+			// later calls bind the existing value and jump past it
+			jmp := c.EmitWithLine(vm.OpBindInitStaticOrJmp, line, name, vm.UnusedOperand(), target)
+			if err := c.Compile(staticVar.Value); err != nil {
 				return err
 			}
-			c.EmitWithLine(vm.OpReturn, uint32(node.Token.Pos.Line), vm.TmpVarOperand(0))
-		} else {
-			// Return null
-			c.EmitWithLine(vm.OpReturn, uint32(node.Token.Pos.Line))
+			c.EmitWithLine(vm.OpBindStatic, line, name, vm.TmpVarOperand(0), target)
+			c.ChangeOperand(jmp, 2, vm.ConstOperand(uint32(c.CurrentPosition())))
+		}
+		return nil
+
+	// Global Variable Import
+	case *ast.UnsetStatement:
+		return c.compileUnset(node)
+
+	case *ast.ConstStatement:
+		return c.compileConstStatement(node)
+
+	case *ast.DeclareStatement:
+		return c.compileDeclare(node)
+
+	case *ast.GlobalStatement:
+		line := uint32(node.Token.Pos.Line)
+		for _, variable := range node.Vars {
+			symbol := c.variableSymbol(variable.Name)
+			name := vm.ConstOperand(uint32(c.AddConstant(variable.Name)))
+			c.EmitWithLine(vm.OpBindGlobal, line, vm.CVOperand(uint32(symbol.Index)), name, vm.UnusedOperand())
 		}
 		return nil
 
@@ -339,45 +600,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 			vm.TmpVarOperand(0))
 		return nil
 
-	// Interpolated String - compile as series of concatenations
+	// Interpolated String - compile into a rope of its parts
 	case *ast.InterpolatedStringExpression:
-		if len(node.Parts) == 0 {
-			// Empty interpolated string - just return empty string
-			constIdx := c.AddConstant("")
-			c.EmitWithLine(vm.OpQMAssign, uint32(node.Token.Pos.Line),
-				vm.ConstOperand(uint32(constIdx)),
-				vm.UnusedOperand(),
-				vm.TmpVarOperand(0))
-			return nil
-		}
-
-		// Compile first part
-		if err := c.Compile(node.Parts[0]); err != nil {
-			return err
-		}
-		// Result in temp var 0
-
-		// Concatenate remaining parts
-		for i := 1; i < len(node.Parts); i++ {
-			// Move previous result from temp 0 to temp 1
-			c.EmitWithLine(vm.OpQMAssign, uint32(node.Token.Pos.Line),
-				vm.TmpVarOperand(0),
-				vm.UnusedOperand(),
-				vm.TmpVarOperand(1))
-
-			// Compile next part into temp var 0
-			if err := c.Compile(node.Parts[i]); err != nil {
-				return err
-			}
-
-			// Concatenate: temp var 1 . temp var 0 -> temp var 0
-			c.EmitWithLine(vm.OpConcat, uint32(node.Token.Pos.Line),
-				vm.TmpVarOperand(1), // Previous result
-				vm.TmpVarOperand(0), // Current part
-				vm.TmpVarOperand(0)) // Result back in temp var 0
-		}
-
-		return nil
+		return c.compileInterpolatedString(node)
 
 	case *ast.BooleanLiteral:
 		constIdx := c.AddConstant(node.Value)
@@ -397,6 +622,16 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 	// Infix Expressions (binary operators)
 	case *ast.InfixExpression:
+		if node.Operator == "??" {
+			return c.compileCoalesce(node)
+		}
+		switch strings.ToLower(node.Operator) {
+		case "&&", "and":
+			return c.compileLogical(node, vm.OpJmpZ)
+		case "||", "or":
+			return c.compileLogical(node, vm.OpJmpNZ)
+		}
+
 		// Optimization: Constant folding
 		// If both operands are constant literals, evaluate at compile time
 		if isConstantLiteral(node.Left) && isConstantLiteral(node.Right) {
@@ -414,28 +649,21 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 		}
 
-		// Optimization: Strength reduction
-		// Replace expensive operations with cheaper equivalents
-		if optimized, err := c.applyStrengthReduction(node); optimized {
-			return err
-		}
-
-		// Normal compilation if not foldable or reducible
-		// Compile left operand
-		if err := c.Compile(node.Left); err != nil {
+		// Normal compilation if not foldable
+		// The left operand is kept out of temp 0, where the right one is
+		// compiled
+		leftTemp, err := c.compileOperand(node.Left)
+		if err != nil {
 			return err
 		}
-		leftTemp := vm.TmpVarOperand(0) // TODO: proper temp tracking
-
-		// Compile right operand
-		if err := c.Compile(node.Right); err != nil {
+		rightTemp, err := c.compileLastOperand(node.Right)
+		if err != nil {
 			return err
 		}
-		rightTemp := vm.TmpVarOperand(1) // TODO: proper temp tracking
 
 		// Emit the appropriate opcode based on operator
 		var opcode vm.Opcode
-		switch node.Operator {
+		switch strings.ToLower(node.Operator) {
 		case "+":
 			opcode = vm.OpAdd
 		case "-":
@@ -482,6 +710,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 			opcode = vm.OpSR
 		case "<=>":
 			opcode = vm.OpSpaceship
+		case "xor":
+			opcode = vm.OpBoolXor
 		default:
 			return fmt.Errorf("unknown infix operator: %s", node.Operator)
 		}
@@ -489,11 +719,18 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.EmitWithLine(opcode, uint32(node.Token.Pos.Line),
 			leftTemp,
 			rightTemp,
-			vm.TmpVarOperand(2)) // Result in temp 2
+			vm.TmpVarOperand(0))
 		return nil
 
 	// Prefix Expressions (unary operators)
 	case *ast.PrefixExpression:
+		if _, ok := incDecOpcodes[node.Operator]; ok {
+			return c.compileIncDec(node)
+		}
+		if node.Operator == "@" {
+			return c.compileSilence(node)
+		}
+
 		// Optimization: Constant folding for unary operations
 		if isConstantLiteral(node.Right) {
 			operandVal, _ := getConstantValue(node.Right)
@@ -521,12 +758,21 @@ func (c *Compiler) Compile(node ast.Node) error {
 		case "!":
 			opcode = vm.OpBoolNot
 		case "-":
-			// Unary minus: 0 - operand
-			constIdx := c.AddConstant(int64(0))
-			c.EmitWithLine(vm.OpSub, uint32(node.Token.Pos.Line),
+			// Unary minus: operand * -1, which keeps -0.0 and turns
+			// -PHP_INT_MIN into a float
+			constIdx := c.AddConstant(int64(-1))
+			c.EmitWithLine(vm.OpMul, uint32(node.Token.Pos.Line),
+				vm.TmpVarOperand(0),
 				vm.ConstOperand(uint32(constIdx)),
+				vm.TmpVarOperand(0))
+			return nil
+		case "+":
+			// Unary plus: operand * 1, converting it to a number
+			constIdx := c.AddConstant(int64(1))
+			c.EmitWithLine(vm.OpMul, uint32(node.Token.Pos.Line),
 				vm.TmpVarOperand(0),
-				vm.TmpVarOperand(1))
+				vm.ConstOperand(uint32(constIdx)),
+				vm.TmpVarOperand(0))
 			return nil
 		case "~":
 			opcode = vm.OpBWNot
@@ -537,17 +783,33 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.EmitWithLine(opcode, uint32(node.Token.Pos.Line),
 			vm.TmpVarOperand(0),
 			vm.UnusedOperand(),
-			vm.TmpVarOperand(1))
+			vm.TmpVarOperand(0))
 		return nil
 
 	case *ast.Variable:
-		// Look up the variable in the symbol table
-		symbol, ok := c.ResolveVariable(node.Name)
-		if !ok {
-			// Variable not defined, define it now (PHP allows implicit declaration)
-			symbol = c.DefineVariable(node.Name)
+		// $GLOBALS is not a variable but a view of the global symbol table
+		if node.Name == "GLOBALS" {
+			c.EmitWithLine(vm.OpFetchGlobals, uint32(node.Token.Pos.Line),
+				vm.UnusedOperand(),
+				vm.UnusedOperand(),
+				vm.TmpVarOperand(0))
+			return nil
+		}
+
+		// $this is not a variable either but the object the method or
+		// closure runs on, if any
+		if node.Name == "this" {
+			c.EmitWithLine(vm.OpFetchThis, uint32(node.Token.Pos.Line),
+				vm.UnusedOperand(),
+				vm.UnusedOperand(),
+				vm.TmpVarOperand(0))
+			return nil
 		}
 
+		// Look up the variable, defining it on first use (PHP allows
+		// implicit declaration)
+		symbol := c.variableSymbol(node.Name)
+
 		// Emit FETCH instruction based on scope
 		switch symbol.Scope {
 		case GlobalScope:
@@ -573,7 +835,50 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 		return nil
 
+	// Variable Variable ($$name, ${expr})
+	case *ast.VariableVariable:
+		return c.compileVariableVariable(node)
+
 	case *ast.AssignmentExpression:
+		if node.Operator == "??=" {
+			return c.compileCoalesceAssignment(node)
+		}
+		if hasNullsafe(node.Left) {
+			return fmt.Errorf("cannot use nullsafe operator in write context")
+		}
+		if opcode, ok := compoundOperators[node.Operator]; ok {
+			return c.compileCompoundAssignment(node, opcode)
+		}
+
+		// Destructuring: list($a, $b) = expr, [$a, $b] = expr
+		if list, ok := node.Left.(*ast.ListExpression); ok {
+			return c.compileListAssignment(list, node.Right)
+		}
+
+		// Element assignment: $var[key] = value
+		if index, ok := node.Left.(*ast.IndexExpression); ok {
+			return c.compileDimAssignment(index, node.Right, uint32(node.Token.Pos.Line))
+		}
+
+		// Handle property assignment: $obj->prop = value; the object and
+		// property name are evaluated before the value
+		if property, ok := node.Left.(*ast.PropertyExpression); ok {
+			objTemp, propTemp, err := c.compilePropertyTarget(property)
+			if err != nil {
+				return err
+			}
+			if err := c.Compile(node.Right); err != nil {
+				return err
+			}
+
+			// Emit ASSIGN_OBJ instruction
+			c.EmitWithLine(vm.OpAssignObj, uint32(node.Token.Pos.Line),
+				objTemp,             // Object
+				propTemp,            // Property name
+				vm.TmpVarOperand(0)) // Value to assign
+			return nil
+		}
+
 		// Compile the right side first
 		if err := c.Compile(node.Right); err != nil {
 			return err
@@ -582,54 +887,35 @@ func (c *Compiler) Compile(node ast.Node) error {
 		// Handle the left side (variable)
 		if variable, ok := node.Left.(*ast.Variable); ok {
 			// Look up or define the variable
-			symbol, ok := c.ResolveVariable(variable.Name)
-			if !ok {
-				symbol = c.DefineVariable(variable.Name)
-			}
+			symbol := c.variableSymbol(variable.Name)
 
 			// Emit ASSIGN instruction
 			c.EmitWithLine(vm.OpAssign, uint32(node.Token.Pos.Line),
-				vm.TmpVarOperand(0), // Value is in temp var 0
 				vm.UnusedOperand(),
+				vm.TmpVarOperand(0), // Value is in temp var 0
 				vm.CVOperand(uint32(symbol.Index))) // Store in compiled variable
 			return nil
 		}
 
-		// Handle property assignment: $obj->prop = value
-		if property, ok := node.Left.(*ast.PropertyExpression); ok {
-			// Value is already compiled (in temp 0)
-			valueTemp := vm.TmpVarOperand(0)
-
-			// Compile the object
-			if err := c.Compile(property.Object); err != nil {
-				return err
-			}
-			objTemp := vm.TmpVarOperand(1)
-
-			// Compile the property (could be identifier or dynamic expression)
-			if err := c.Compile(property.Property); err != nil {
-				return err
-			}
-			propTemp := vm.TmpVarOperand(2)
+		// $$name = value, ${expr} = value
+		if variable, ok := node.Left.(*ast.VariableVariable); ok {
+			return c.compileVariableVariableAssignment(variable, uint32(node.Token.Pos.Line))
+		}
 
-			// Emit ASSIGN_OBJ instruction
-			c.EmitWithLine(vm.OpAssignObj, uint32(node.Token.Pos.Line),
-				objTemp,   // Object
-				propTemp,  // Property name
-				valueTemp) // Value to assign
-			return nil
+		// Class::$prop = value
+		if property, ok := node.Left.(*ast.StaticPropertyExpression); ok {
+			return c.compileStaticPropertyAssignment(property, uint32(node.Token.Pos.Line))
 		}
 
 		return fmt.Errorf("assignment to non-variable not yet implemented")
 
-	// Identifier (convert to string constant)
+	// Identifier (a constant: FOO, \Ns\FOO)
 	case *ast.Identifier:
-		constIdx := c.AddConstant(node.Value)
-		c.EmitWithLine(vm.OpQMAssign, uint32(node.Token.Pos.Line),
-			vm.ConstOperand(uint32(constIdx)),
-			vm.UnusedOperand(),
-			vm.TmpVarOperand(0))
-		return nil
+		return c.compileConstantFetch(node)
+
+	// Magic Constant (__LINE__, __CLASS__, ...)
+	case *ast.MagicConstant:
+		return c.compileMagicConstant(node)
 
 	// Grouped Expression (just compile the inner expression)
 	case *ast.GroupedExpression:
@@ -637,11 +923,18 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 	// Closure Expression (anonymous function)
 	case *ast.ClosureExpression:
-		// Remember closure start position
-		closureStart := c.CurrentPosition()
+		params, err := c.parameterDefs(node.Parameters)
+		if err != nil {
+			return err
+		}
+
+		// The body is compiled into an op array of its own
+		outerOpArray := c.enterOpArray()
 
 		// Enter new scope for closure
 		c.EnterScope()
+		c.enterFunction(nil)
+		outerScope := c.enterFunctionScope("{closure}")
 
 		// Emit RECV opcodes for each parameter
 		for i, param := range node.Parameters {
@@ -667,12 +960,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 					vm.CVOperand(uint32(symbol.Index)))      // Store in compiled variable
 			} else {
 				// RECV for required parameters
-				recvOp := vm.OpRecv
-				if param.ByRef {
-					recvOp = vm.OpSendRef // Use SEND_REF for by-reference parameters
-				}
-
-				c.EmitWithLine(recvOp, uint32(node.Token.Pos.Line),
+				c.EmitWithLine(vm.OpRecv, uint32(node.Token.Pos.Line),
 					vm.ConstOperand(uint32(i)),    // Parameter index
 					vm.UnusedOperand(),
 					vm.CVOperand(uint32(symbol.Index))) // Store in compiled variable
@@ -694,51 +982,64 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		// Exit closure scope
+		fn := &vm.CompiledFunction{
+			Name:        "{closure}",
+			NumLocals:   c.symbolTable.NumDefinitions(),
+			NumParams:   len(node.Parameters),
+			Variables:   c.symbolTable.VariableNames(),
+			Parameters:  params,
+			ReturnByRef: node.ByRef,
+			StrictTypes: c.strictTypes,
+		}
+		c.exitFunction()
 		c.ExitScope()
+		c.scope = outerScope
+		c.exitOpArray(outerOpArray, fn)
 
-		// Closure end position
-		closureEnd := c.CurrentPosition()
-
-		// DECLARE_LAMBDA_FUNCTION to create the closure object
-		// Store closure metadata: num params, start pos, end pos, flags
+		// DECLARE_LAMBDA_FUNCTION to create the closure object from the
+		// op array compiled above
 		flags := uint32(0)
 		if node.Static {
-			flags |= 1 // Static flag
+			flags |= vm.LambdaStatic
 		}
 		if node.ByRef {
-			flags |= 2 // Return by reference flag
+			flags |= vm.LambdaByRef
 		}
 
 		c.EmitWithExtended(vm.OpDeclareLambdaFunction, uint32(node.Token.Pos.Line),
-			uint32(len(node.Parameters)),  // Number of parameters
-			vm.ConstOperand(uint32(flags)), // Flags (static, byref)
-			vm.ConstOperand(uint32(closureStart)), // Closure start position
-			vm.ConstOperand(uint32(closureEnd)))   // Closure end position
+			flags, // Flags (static, byref)
+			vm.ConstOperand(uint32(c.AddConstant(&vm.FunctionDecl{Function: fn}))),
+			vm.UnusedOperand(),
+			vm.TmpVarOperand(0)) // Closure object in temp 0
 
 		// Bind captured variables from use clause
 		for _, useVar := range node.Use {
-			// Get the variable from parent scope
-			varNameIdx := c.AddConstant(useVar.Variable.Name)
-			byRefFlag := uint32(0)
+			flags := uint32(0)
 			if useVar.ByRef {
-				byRefFlag = 1
+				flags |= vm.BindLexicalRef
 			}
-
-			c.EmitWithLine(vm.OpBindLexical, uint32(node.Token.Pos.Line),
-				vm.ConstOperand(uint32(varNameIdx)), // Variable name
-				vm.ConstOperand(byRefFlag),           // By reference flag
-				vm.TmpVarOperand(0))                  // Closure object in temp 0
+			c.EmitWithExtended(vm.OpBindLexical, uint32(node.Token.Pos.Line),
+				flags,
+				c.variableOperand(useVar.Variable),                          // Variable of the declaring scope
+				vm.ConstOperand(uint32(c.AddConstant(useVar.Variable.Name))), // Variable name
+				vm.TmpVarOperand(0))                                         // Closure object in temp 0
 		}
 
 		return nil
 
 	// Arrow Function Expression (PHP 7.4+)
 	case *ast.ArrowFunctionExpression:
-		// Remember arrow function start position
-		arrowStart := c.CurrentPosition()
+		params, err := c.parameterDefs(node.Parameters)
+		if err != nil {
+			return err
+		}
+
+		// The body is compiled into an op array of its own
+		outerOpArray := c.enterOpArray()
 
 		// Enter new scope for arrow function
 		c.EnterScope()
+		outerScope := c.enterFunctionScope("{closure}")
 
 		// Emit RECV opcodes for each parameter
 		for i, param := range node.Parameters {
@@ -759,11 +1060,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 					vm.TmpVarOperand(0),
 					vm.CVOperand(uint32(symbol.Index)))
 			} else {
-				recvOp := vm.OpRecv
-				if param.ByRef {
-					recvOp = vm.OpSendRef
-				}
-				c.EmitWithLine(recvOp, uint32(node.Token.Pos.Line),
+				c.EmitWithLine(vm.OpRecv, uint32(node.Token.Pos.Line),
 					vm.ConstOperand(uint32(i)),
 					vm.UnusedOperand(),
 					vm.CVOperand(uint32(symbol.Index)))
@@ -785,267 +1082,280 @@ func (c *Compiler) Compile(node ast.Node) error {
 			vm.UnusedOperand(),
 			vm.UnusedOperand())
 
+		// The variables of the body other than the parameters come from
+		// the declaring scope
+		var captured []string
+		for i, name := range c.symbolTable.VariableNames() {
+			if i >= len(node.Parameters) && name != "this" && name != "" {
+				captured = append(captured, name)
+			}
+		}
+
 		// Exit arrow function scope
+		fn := &vm.CompiledFunction{
+			Name:        "{closure}",
+			NumLocals:   c.symbolTable.NumDefinitions(),
+			NumParams:   len(node.Parameters),
+			Variables:   c.symbolTable.VariableNames(),
+			Parameters:  params,
+			ReturnByRef: node.ByRef,
+			StrictTypes: c.strictTypes,
+		}
 		c.ExitScope()
-
-		// Arrow function end position
-		arrowEnd := c.CurrentPosition()
+		c.scope = outerScope
+		c.exitOpArray(outerOpArray, fn)
 
 		// DECLARE_LAMBDA_FUNCTION to create the arrow function object
 		flags := uint32(0)
 		if node.Static {
-			flags |= 1 // Static flag
+			flags |= vm.LambdaStatic
 		}
 		if node.ByRef {
-			flags |= 2 // Return by reference flag
+			flags |= vm.LambdaByRef
 		}
 
 		c.EmitWithExtended(vm.OpDeclareLambdaFunction, uint32(node.Token.Pos.Line),
-			uint32(len(node.Parameters)),           // Number of parameters
-			vm.ConstOperand(uint32(flags)),          // Flags (static, byref)
-			vm.ConstOperand(uint32(arrowStart)),     // Arrow function start position
-			vm.ConstOperand(uint32(arrowEnd)))       // Arrow function end position
-
-		// Arrow functions auto-capture variables from parent scope
-		// For now, we'll skip auto-capture implementation (would need sophisticated analysis)
-		// In a full implementation, we'd analyze node.Body to find referenced variables
+			flags, // Flags (static, byref)
+			vm.ConstOperand(uint32(c.AddConstant(&vm.FunctionDecl{Function: fn}))),
+			vm.UnusedOperand(),
+			vm.TmpVarOperand(0)) // Arrow function object in temp 0
+
+		// Arrow functions capture the values of the variables they use
+		for _, name := range captured {
+			c.EmitWithExtended(vm.OpBindLexical, uint32(node.Token.Pos.Line),
+				vm.BindLexicalImplicit,
+				vm.CVOperand(uint32(c.variableSymbol(name).Index)),
+				vm.ConstOperand(uint32(c.AddConstant(name))),
+				vm.TmpVarOperand(0))
+		}
 
 		return nil
 
 	// Array Literal
 	case *ast.ArrayExpression:
 		// Initialize empty array
-		c.EmitWithLine(vm.OpInitArray, uint32(node.Token.Pos.Line),
+		line := uint32(node.Token.Pos.Line)
+		array := c.newTemp()
+		c.EmitWithLine(vm.OpInitArray, line,
 			vm.UnusedOperand(),
 			vm.UnusedOperand(),
-			vm.TmpVarOperand(0)) // Array in temp var 0
+			array)
 
 		// Add elements to array
 		for _, elem := range node.Elements {
-			// Compile the value
-			if err := c.Compile(elem.Value); err != nil {
-				return err
+			if elem.Value == nil {
+				return fmt.Errorf("cannot use empty array elements in arrays")
+			}
+			if elem.ByRef {
+				return fmt.Errorf("references in array literals are not yet implemented")
 			}
-			valueTemp := vm.TmpVarOperand(1) // Value in temp 1
 
-			// If there's a key, compile it
+			// The key is evaluated before the value
+			key := vm.UnusedOperand()
 			if elem.Key != nil {
-				if err := c.Compile(elem.Key); err != nil {
+				var err error
+				if key, err = c.compileOperand(elem.Key); err != nil {
 					return err
 				}
-				keyTemp := vm.TmpVarOperand(2) // Key in temp 2
-
-				// ADD_ARRAY_ELEMENT with key: array[key] = value
-				c.EmitWithLine(vm.OpAddArrayElement, uint32(node.Token.Pos.Line),
-					valueTemp,
-					keyTemp,
-					vm.TmpVarOperand(0)) // Result array in temp 0
-			} else {
-				// ADD_ARRAY_ELEMENT without key: array[] = value
-				c.EmitWithLine(vm.OpAddArrayElement, uint32(node.Token.Pos.Line),
-					valueTemp,
-					vm.UnusedOperand(),
-					vm.TmpVarOperand(0)) // Result array in temp 0
 			}
+			value, err := c.compileLastOperand(elem.Value)
+			if err != nil {
+				return err
+			}
+
+			// ADD_ARRAY_ELEMENT: array[key] = value, or array[] = value
+			// without a key
+			c.EmitWithLine(vm.OpAddArrayElement, line, value, key, array)
 		}
+
+		// Array in temp 0
+		c.EmitWithLine(vm.OpQMAssign, line, array, vm.UnusedOperand(), vm.TmpVarOperand(0))
 		return nil
 
 	// Array Access
 	case *ast.IndexExpression:
+		if hasNullsafe(node) {
+			return c.compileNullsafeChain(node)
+		}
+
+		if node.Index == nil {
+			return fmt.Errorf("cannot use [] for reading")
+		}
+
 		// Compile the array/string
-		if err := c.Compile(node.Left); err != nil {
+		arrayTemp, err := c.containerOperand(node.Left)
+		if err != nil {
 			return err
 		}
-		arrayTemp := vm.TmpVarOperand(0)
 
 		// Compile the index
-		if err := c.Compile(node.Index); err != nil {
+		indexTemp, err := c.compileLastOperand(node.Index)
+		if err != nil {
 			return err
 		}
-		indexTemp := vm.TmpVarOperand(1)
 
 		// Emit FETCH_DIM_R: result = array[index]
 		c.EmitWithLine(vm.OpFetchDimR, uint32(node.Token.Pos.Line),
 			arrayTemp,
 			indexTemp,
-			vm.TmpVarOperand(2)) // Result in temp 2
+			vm.TmpVarOperand(0)) // Result in temp 0
 		return nil
 
 	// Property Access
 	case *ast.PropertyExpression:
-		// Compile the object
-		if err := c.Compile(node.Object); err != nil {
-			return err
-		}
-		objTemp := vm.TmpVarOperand(0)
+		// $obj->prop, $obj->$name, $obj->{expr} and the chains they end
+		return c.compileNullsafeChain(node)
 
-		// Compile the property (could be identifier or dynamic expression)
-		if err := c.Compile(node.Property); err != nil {
-			return err
+	// Function Call
+	case *ast.CallExpression:
+		// First-class callable syntax: strlen(...)
+		if ast.IsFirstClassCallable(node.Arguments) {
+			if err := c.compileInitFcall(node, 0); err != nil {
+				return err
+			}
+			c.EmitWithLine(vm.OpCallableConvert, uint32(node.Token.Pos.Line),
+				vm.UnusedOperand(),
+				vm.UnusedOperand(),
+				vm.TmpVarOperand(0)) // Closure in temp 0
+			return nil
 		}
-		propTemp := vm.TmpVarOperand(1)
 
-		// Emit FETCH_OBJ_R: result = obj->prop
-		c.EmitWithLine(vm.OpFetchObjR, uint32(node.Token.Pos.Line),
-			objTemp,
-			propTemp,
-			vm.TmpVarOperand(2)) // Result in temp 2
-		return nil
+		// is_int($x), intval($x), etc. need no call
+		if c.compileTypeFunction(node) {
+			return nil
+		}
 
-	// Function Call
-	case *ast.CallExpression:
 		// For now, we'll handle simple function calls by name
 		// Full implementation with dynamic calls will come later
 
-		// Compile arguments first
-		for _, arg := range node.Arguments {
-			if err := c.Compile(arg); err != nil {
-				return err
-			}
-			// TODO: Push arguments onto stack properly
+		params, known := c.callSignature(node)
+		if err := checkArguments(node.Arguments, params, known); err != nil {
+			return err
 		}
 
-		// Compile the function expression
-		if err := c.Compile(node.Function); err != nil {
+		// Initialize function call
+		if err := c.compileInitFcall(node, len(node.Arguments)); err != nil {
 			return err
 		}
-		funcTemp := vm.TmpVarOperand(0)
 
-		// Initialize function call
-		c.EmitWithLine(vm.OpInitFcallByName, uint32(node.Token.Pos.Line),
-			funcTemp,
-			vm.ConstOperand(uint32(len(node.Arguments))), // Argument count
-			vm.UnusedOperand())
+		// Send the arguments
+		if err := c.compileArguments(node.Arguments, uint32(node.Token.Pos.Line), c.refParams(node)); err != nil {
+			return err
+		}
 
 		// Execute function call
 		c.EmitWithLine(vm.OpDoFcall, uint32(node.Token.Pos.Line),
 			vm.UnusedOperand(),
 			vm.UnusedOperand(),
-			vm.TmpVarOperand(1)) // Result in temp 1
+			vm.TmpVarOperand(0)) // Result in temp 0
 		return nil
 
+	// Nullsafe Property Access and Method Calls
+	case *ast.NullsafePropertyExpression:
+		return c.compileNullsafeChain(node)
+
 	// Method Call
 	case *ast.MethodCallExpression:
-		// Compile the object
-		if err := c.Compile(node.Object); err != nil {
-			return err
+		// $obj->method(), $obj->$name() and the chains they end
+		return c.compileNullsafeChain(node)
+
+	// Static Property Access (Class::$property)
+	case *ast.StaticPropertyExpression:
+		// Foo::class resolves to the fully qualified class name at compile time
+		if ident, ok := node.Property.(*ast.Identifier); ok && strings.EqualFold(ident.Value, "class") {
+			return c.compileClassNameConstant(node)
+		}
+		// Foo::BAR is a class constant or enum case
+		if ident, ok := node.Property.(*ast.Identifier); ok {
+			return c.compileClassConstant(node, ident)
 		}
-		objTemp := vm.TmpVarOperand(0)
 
-		// Compile the method name (could be identifier or dynamic)
-		if err := c.Compile(node.Method); err != nil {
-			return err
+		// Foo::$bar, $class::$bar, Foo::$$name
+		return c.compileStaticProperty(node)
+
+	// Static Method Call (Class::method())
+	case *ast.StaticCallExpression:
+		// Named classes (self, parent and static included, which the VM
+		// resolves in the calling scope) and methods are constant operands
+		var classTemp vm.Operand
+		if ident, ok := node.Class.(*ast.Identifier); ok {
+			classTemp = vm.ConstOperand(uint32(c.AddConstant(c.resolveClassName(ident.Value))))
+		} else {
+			var err error
+			if classTemp, err = c.compileOperand(node.Class); err != nil {
+				return err
+			}
 		}
-		methodTemp := vm.TmpVarOperand(1)
 
-		// Compile arguments
-		for _, arg := range node.Arguments {
-			if err := c.Compile(arg); err != nil {
+		var methodTemp vm.Operand
+		if ident, ok := node.Method.(*ast.Identifier); ok {
+			methodTemp = vm.ConstOperand(uint32(c.AddConstant(ident.Value)))
+		} else {
+			var err error
+			if methodTemp, err = c.compileLastOperand(node.Method); err != nil {
 				return err
 			}
-			// TODO: Push arguments onto stack properly
 		}
 
-		// Initialize method call
-		c.EmitWithLine(vm.OpInitMethodCall, uint32(node.Token.Pos.Line),
-			objTemp,
+		// First-class callable syntax: Foo::bar(...)
+		if ast.IsFirstClassCallable(node.Arguments) {
+			c.EmitWithLine(vm.OpInitStaticMethodCall, uint32(node.Token.Pos.Line),
+				classTemp,
+				methodTemp,
+				vm.UnusedOperand())
+			c.EmitWithLine(vm.OpCallableConvert, uint32(node.Token.Pos.Line),
+				vm.UnusedOperand(),
+				vm.UnusedOperand(),
+				vm.TmpVarOperand(0)) // Closure in temp 0
+			return nil
+		}
+
+		if err := checkArguments(node.Arguments, nil, false); err != nil {
+			return err
+		}
+
+		// Initialize static method call
+		c.EmitWithLine(vm.OpInitStaticMethodCall, uint32(node.Token.Pos.Line),
+			classTemp,
 			methodTemp,
 			vm.UnusedOperand())
 
+		// Send the arguments
+		if err := c.compileArguments(node.Arguments, uint32(node.Token.Pos.Line), nil); err != nil {
+			return err
+		}
+
 		// Execute method call with argument count in extended value
 		c.EmitWithExtended(vm.OpDoFcall, uint32(node.Token.Pos.Line),
 			uint32(len(node.Arguments)),
 			vm.UnusedOperand(),
 			vm.UnusedOperand(),
-			vm.TmpVarOperand(2)) // Result in temp 2
+			vm.TmpVarOperand(0)) // Result in temp 0
 		return nil
 
-	// Static Property Access (Class::$property)
-	case *ast.StaticPropertyExpression:
-		// Compile the class name (could be identifier or dynamic)
-		if err := c.Compile(node.Class); err != nil {
-			return err
-		}
-		classTemp := vm.TmpVarOperand(0)
-
-		// Compile the property (usually a variable)
-		if err := c.Compile(node.Property); err != nil {
-			return err
-		}
-		propTemp := vm.TmpVarOperand(1)
-
-		// Fetch static property
-		c.EmitWithLine(vm.OpFetchStaticPropR, uint32(node.Token.Pos.Line),
-			classTemp,
-			propTemp,
-			vm.TmpVarOperand(2)) // Result in temp 2
-		return nil
-
-	// Static Method Call (Class::method())
-	case *ast.StaticCallExpression:
-		// Compile the class name (could be identifier or dynamic)
-		if err := c.Compile(node.Class); err != nil {
-			return err
-		}
-		classTemp := vm.TmpVarOperand(0)
-
-		// Compile the method name (could be identifier or dynamic)
-		if err := c.Compile(node.Method); err != nil {
-			return err
-		}
-		methodTemp := vm.TmpVarOperand(1)
-
-		// Compile arguments
-		for _, arg := range node.Arguments {
-			if err := c.Compile(arg); err != nil {
-				return err
-			}
-			// TODO: Push arguments onto stack properly
-		}
-
-		// Initialize static method call
-		c.EmitWithLine(vm.OpInitStaticMethodCall, uint32(node.Token.Pos.Line),
-			classTemp,
-			methodTemp,
-			vm.UnusedOperand())
-
-		// Execute method call with argument count in extended value
-		c.EmitWithExtended(vm.OpDoFcall, uint32(node.Token.Pos.Line),
-			uint32(len(node.Arguments)),
-			vm.UnusedOperand(),
-			vm.UnusedOperand(),
-			vm.TmpVarOperand(2)) // Result in temp 2
-		return nil
-
-	// Ternary Operator
-	case *ast.TernaryExpression:
-		// Compile the condition
-		if err := c.Compile(node.Condition); err != nil {
+	// Ternary Operator
+	case *ast.TernaryExpression:
+		// Compile the condition
+		if err := c.Compile(node.Condition); err != nil {
 			return err
 		}
 
 		// Short ternary form: $x ?: $y (if $x is falsy, use $y)
 		if node.Consequence == nil {
-			// Use JMP_SET: if (condition) { result = condition; goto end; }
-			// Emit JMP_SET with placeholder address
-			jmpSetPos := c.EmitWithLine(vm.OpJmpSet, uint32(node.Token.Pos.Line),
+			// JMPNZ end keeps a truthy condition as the result
+			jmpnzPos := c.EmitWithLine(vm.OpJmpNZ, uint32(node.Token.Pos.Line),
 				vm.TmpVarOperand(0),
 				vm.UnusedOperand(),
-				vm.TmpVarOperand(1)) // Result in temp 1
+				vm.UnusedOperand())
 
-			// Compile alternative (used if condition is falsy)
+			// Compile alternative (used if condition is falsy) into temp 0
 			if err := c.Compile(node.Alternative); err != nil {
 				return err
 			}
-			// Move alternative to result
-			c.EmitWithLine(vm.OpQMAssign, uint32(node.Token.Pos.Line),
-				vm.TmpVarOperand(0),
-				vm.UnusedOperand(),
-				vm.TmpVarOperand(1))
 
-			// Patch JMP_SET to jump here
+			// Patch JMPNZ to jump here
 			endPos := c.CurrentPosition()
-			c.ChangeOperand(jmpSetPos, 2, vm.ConstOperand(uint32(endPos)))
+			c.ChangeOperand(jmpnzPos, 2, vm.ConstOperand(uint32(endPos)))
 			return nil
 		}
 
@@ -1056,15 +1366,10 @@ func (c *Compiler) Compile(node ast.Node) error {
 			vm.UnusedOperand(),
 			vm.UnusedOperand())
 
-		// Compile consequence (true branch)
+		// Compile consequence (true branch) into temp 0
 		if err := c.Compile(node.Consequence); err != nil {
 			return err
 		}
-		// Move consequence to result
-		c.EmitWithLine(vm.OpQMAssign, uint32(node.Token.Pos.Line),
-			vm.TmpVarOperand(0),
-			vm.UnusedOperand(),
-			vm.TmpVarOperand(1)) // Result in temp 1
 
 		// JMP to end with placeholder
 		jmpEndPos := c.EmitWithLine(vm.OpJmp, uint32(node.Token.Pos.Line),
@@ -1074,23 +1379,22 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 		// Patch JMPZ to jump to alternative
 		altPos := c.CurrentPosition()
-		c.ChangeOperand(jmpzPos, 1, vm.ConstOperand(uint32(altPos)))
+		c.ChangeOperand(jmpzPos, 2, vm.ConstOperand(uint32(altPos)))
 
-		// Compile alternative (false branch)
+		// Compile alternative (false branch) into temp 0
 		if err := c.Compile(node.Alternative); err != nil {
 			return err
 		}
-		// Move alternative to result
-		c.EmitWithLine(vm.OpQMAssign, uint32(node.Token.Pos.Line),
-			vm.TmpVarOperand(0),
-			vm.UnusedOperand(),
-			vm.TmpVarOperand(1))
 
 		// Patch JMP to jump to end
 		endPos := c.CurrentPosition()
 		c.ChangeOperand(jmpEndPos, 1, vm.ConstOperand(uint32(endPos)))
 		return nil
 
+	// Match Expression
+	case *ast.MatchExpression:
+		return c.compileMatch(node)
+
 	// Type Cast
 	case *ast.CastExpression:
 		// Compile the expression to cast
@@ -1124,52 +1428,124 @@ func (c *Compiler) Compile(node ast.Node) error {
 			castType,
 			vm.TmpVarOperand(0),
 			vm.UnusedOperand(),
-			vm.TmpVarOperand(1)) // Result in temp 1
+			vm.TmpVarOperand(0)) // Result in temp 0
 		return nil
 
-	// Instanceof
-	case *ast.InstanceofExpression:
-		// Compile the left side (object)
-		if err := c.Compile(node.Left); err != nil {
-			return err
+	// Include/require
+	case *ast.IssetExpression:
+		return c.compileIsset(node)
+
+	case *ast.EmptyExpression:
+		return c.compileEmpty(node)
+
+	case *ast.ExitExpression:
+		status := vm.UnusedOperand()
+		if node.Status != nil {
+			if err := c.Compile(node.Status); err != nil {
+				return err
+			}
+			status = vm.TmpVarOperand(0)
 		}
-		objTemp := vm.TmpVarOperand(0)
+		c.EmitWithLine(vm.OpExit, uint32(node.Token.Pos.Line),
+			status,
+			vm.UnusedOperand(),
+			vm.UnusedOperand())
+		return nil
 
-		// Compile the right side (class name/expression)
-		if err := c.Compile(node.Right); err != nil {
+	case *ast.IncludeExpression:
+		if err := c.Compile(node.Path); err != nil {
 			return err
 		}
-		classTemp := vm.TmpVarOperand(1)
+
+		var kind uint32
+		switch node.Kind {
+		case "include":
+			kind = vm.IncludeKindInclude
+		case "include_once":
+			kind = vm.IncludeKindIncludeOnce
+		case "require":
+			kind = vm.IncludeKindRequire
+		case "require_once":
+			kind = vm.IncludeKindRequireOnce
+		default:
+			return fmt.Errorf("unknown include kind: %s", node.Kind)
+		}
+
+		c.EmitWithExtended(vm.OpIncludeOrEval, uint32(node.Token.Pos.Line),
+			kind,
+			vm.TmpVarOperand(0),
+			vm.UnusedOperand(),
+			vm.TmpVarOperand(0))
+		return nil
+
+	// Instanceof
+	case *ast.InstanceofExpression:
+		// A class name is a constant operand; other class expressions
+		// are evaluated at runtime, with the object kept out of temp 0
+		var objTemp, classTemp vm.Operand
+		if ident, ok := node.Right.(*ast.Identifier); ok {
+			if err := c.Compile(node.Left); err != nil {
+				return err
+			}
+			objTemp = vm.TmpVarOperand(0)
+			classTemp = vm.ConstOperand(uint32(c.AddConstant(c.resolveClassName(ident.Value))))
+		} else {
+			var err error
+			if objTemp, err = c.compileOperand(node.Left); err != nil {
+				return err
+			}
+			if err := c.Compile(node.Right); err != nil {
+				return err
+			}
+			classTemp = vm.TmpVarOperand(0)
+		}
 
 		// Emit INSTANCEOF instruction
 		c.EmitWithLine(vm.OpInstanceof, uint32(node.Token.Pos.Line),
 			objTemp,
 			classTemp,
-			vm.TmpVarOperand(2)) // Result in temp 2
+			vm.TmpVarOperand(0)) // Result in temp 0
 		return nil
 
 	// New Expression (object instantiation)
 	case *ast.NewExpression:
+		line := uint32(node.Token.Pos.Line)
+
 		// Compile class name
-		if err := c.Compile(node.Class); err != nil {
+		classTemp := vm.TmpVarOperand(0)
+		if ident, ok := node.Class.(*ast.Identifier); ok {
+			classTemp = vm.ConstOperand(uint32(c.AddConstant(c.resolveClassName(ident.Value))))
+		} else if err := c.compileClassRef(node.Class); err != nil {
 			return err
 		}
-		classTemp := vm.TmpVarOperand(0)
 
-		// Compile constructor arguments
-		for _, arg := range node.Arguments {
-			if err := c.Compile(arg); err != nil {
-				return err
-			}
-			// TODO: Push arguments onto stack properly
+		if err := checkArguments(node.Arguments, nil, false); err != nil {
+			return err
 		}
 
-		// NEW instruction with argument count in extended value
-		c.EmitWithExtended(vm.OpNew, uint32(node.Token.Pos.Line),
+		// NEW initializes the constructor call, whose arguments and
+		// DO_FCALL follow; it jumps past them when there is no
+		// constructor
+		object := c.newTemp()
+		newPos := c.EmitWithExtended(vm.OpNew, line,
 			uint32(len(node.Arguments)),
 			classTemp,
+			vm.ConstOperand(0), // Patched below
+			object)
+		if err := c.compileArguments(node.Arguments, line, nil); err != nil {
+			return err
+		}
+		c.EmitWithExtended(vm.OpDoFcall, line,
+			uint32(len(node.Arguments)),
+			vm.UnusedOperand(),
 			vm.UnusedOperand(),
-			vm.TmpVarOperand(1)) // New object in temp 1
+			vm.UnusedOperand())
+		c.ChangeOperand(newPos, 2, vm.ConstOperand(uint32(c.CurrentPosition())))
+
+		// New object in temp 0. The temporary NEW created it in must not
+		// keep it alive: it is destroyed once its last holder drops it.
+		c.EmitWithLine(vm.OpQMAssign, line, object, vm.UnusedOperand(), vm.TmpVarOperand(0))
+		c.EmitWithLine(vm.OpFree, line, object, vm.UnusedOperand(), vm.UnusedOperand())
 		return nil
 
 	// ========================================
@@ -1202,7 +1578,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 		// Patch JMPZ to point here
 		altStart := c.CurrentPosition()
-		c.ChangeOperand(jmpzPos, 1, vm.ConstOperand(uint32(altStart)))
+		c.ChangeOperand(jmpzPos, 2, vm.ConstOperand(uint32(altStart)))
 
 		// Track positions for elseif jumps
 		elseifJumps := []int{}
@@ -1234,7 +1610,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 			// Patch JMPZ to next clause
 			nextClause := c.CurrentPosition()
-			c.ChangeOperand(elseifJmpz, 1, vm.ConstOperand(uint32(nextClause)))
+			c.ChangeOperand(elseifJmpz, 2, vm.ConstOperand(uint32(nextClause)))
 		}
 
 		// Compile alternative (else) if present
@@ -1282,7 +1658,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 		// Patch JMPZ to jump here (end of loop)
 		endPos := c.CurrentPosition()
-		c.ChangeOperand(jmpzPos, 1, vm.ConstOperand(uint32(endPos)))
+		c.ChangeOperand(jmpzPos, 2, vm.ConstOperand(uint32(endPos)))
 
 		// Exit loop and patch break/continue
 		c.ExitLoop(endPos)
@@ -1303,33 +1679,23 @@ func (c *Compiler) Compile(node ast.Node) error {
 		condStart := c.CurrentPosition()
 		c.EnterLoop(condStart)
 
-		// Compile condition (if any)
+		// Compile condition (if any): all the expressions are evaluated
+		// and, as in PHP, the last one decides
 		var jmpzPos int
 		if len(node.Condition) > 0 {
-			// Evaluate all conditions with AND logic
 			for i, cond := range node.Condition {
 				if err := c.Compile(cond); err != nil {
 					return err
 				}
 				if i < len(node.Condition)-1 {
-					// Not the last condition, short-circuit if false
-					jmpz := c.EmitWithLine(vm.OpJmpZ, uint32(node.Token.Pos.Line),
-						vm.TmpVarOperand(0),
-						vm.UnusedOperand(),
-						vm.UnusedOperand())
-					if i == 0 {
-						jmpzPos = jmpz
-					}
+					c.Emit(vm.OpFree, vm.TmpVarOperand(0))
 				}
 			}
-			// Final JMPZ to exit loop
-			finalJmpz := c.EmitWithLine(vm.OpJmpZ, uint32(node.Token.Pos.Line),
+			// JMPZ to exit loop
+			jmpzPos = c.EmitWithLine(vm.OpJmpZ, uint32(node.Token.Pos.Line),
 				vm.TmpVarOperand(0),
 				vm.UnusedOperand(),
 				vm.UnusedOperand())
-			if len(node.Condition) == 1 {
-				jmpzPos = finalJmpz
-			}
 		}
 
 		// Compile loop body
@@ -1358,7 +1724,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		// Patch condition JMPZ to jump here (end of loop)
 		endPos := c.CurrentPosition()
 		if len(node.Condition) > 0 {
-			c.ChangeOperand(jmpzPos, 1, vm.ConstOperand(uint32(endPos)))
+			c.ChangeOperand(jmpzPos, 2, vm.ConstOperand(uint32(endPos)))
 		}
 
 		// Update loop context to use increment position for continue
@@ -1372,62 +1738,74 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 	// Foreach Loop
 	case *ast.ForeachStatement:
-		// Compile array expression
-		if err := c.Compile(node.Array); err != nil {
-			return err
-		}
-		arrayTemp := vm.TmpVarOperand(0)
+		line := uint32(node.Token.Pos.Line)
 
-		// Choose reset opcode based on by-ref
+		// Choose reset opcode based on by-ref; a loop by reference walks
+		// the variable itself
 		resetOp := vm.OpFeResetR
 		fetchOp := vm.OpFeFetchR
+		arrayTemp := vm.TmpVarOperand(0)
 		if node.ByRef {
 			resetOp = vm.OpFeResetRW
 			fetchOp = vm.OpFeFetchRW
 		}
+		if variable, ok := node.Array.(*ast.Variable); ok && node.ByRef {
+			arrayTemp = c.variableOperand(variable)
+		} else if err := c.Compile(node.Array); err != nil {
+			return err
+		}
 
 		// FE_RESET: Initialize foreach iterator
-		c.EmitWithLine(resetOp, uint32(node.Token.Pos.Line),
+		iterator := c.newTemp()
+		c.EmitWithLine(resetOp, line,
 			arrayTemp,
 			vm.UnusedOperand(),
-			vm.TmpVarOperand(1)) // Iterator in temp 1
+			iterator)
 
 		// Remember start position for continue
 		startPos := c.CurrentPosition()
 		c.EnterLoop(startPos)
-
-		// FE_FETCH: Fetch next element (jumps to end if done)
-		jmpEndPos := c.EmitWithLine(fetchOp, uint32(node.Token.Pos.Line),
-			vm.TmpVarOperand(1), // Iterator
+		c.CurrentLoop().iterator = &iterator
+
+		// FE_FETCH: Fetch next element (jumps to end if done) into the
+		// value temporary, and its key into the one after it
+		value := c.newTemp()
+		key := c.newTemp()
+		jmpEndPos := c.EmitWithLine(fetchOp, line,
+			iterator,
 			vm.UnusedOperand(),
-			vm.TmpVarOperand(2)) // Fetched value
+			value)
 
 		// Assign key if present
 		if node.Key != nil {
 			if keyVar, ok := node.Key.(*ast.Variable); ok {
-				symbol, ok := c.ResolveVariable(keyVar.Name)
-				if !ok {
-					symbol = c.DefineVariable(keyVar.Name)
-				}
-				// Assign key (stored in temp 3 by FE_FETCH)
-				c.EmitWithLine(vm.OpAssign, uint32(node.Token.Pos.Line),
-					vm.TmpVarOperand(3),
+				c.EmitWithLine(vm.OpAssign, line,
 					vm.UnusedOperand(),
-					vm.CVOperand(uint32(symbol.Index)))
+					key,
+					c.variableOperand(keyVar))
+			}
+		}
+
+		// Destructure value: foreach ($rows as [$id, $name])
+		if list, ok := node.Value.(*ast.ListExpression); ok {
+			if err := c.compileList(list, value, node.ByRef, 0); err != nil {
+				return err
 			}
 		}
 
-		// Assign value
+		// Assign value, binding the variable to the element by reference
 		if valueVar, ok := node.Value.(*ast.Variable); ok {
-			symbol, ok := c.ResolveVariable(valueVar.Name)
-			if !ok {
-				symbol = c.DefineVariable(valueVar.Name)
+			if node.ByRef {
+				c.EmitWithLine(vm.OpAssignRef, line,
+					c.variableOperand(valueVar),
+					value,
+					vm.UnusedOperand())
+			} else {
+				c.EmitWithLine(vm.OpAssign, line,
+					vm.UnusedOperand(),
+					value,
+					c.variableOperand(valueVar))
 			}
-			// Assign value (in temp 2)
-			c.EmitWithLine(vm.OpAssign, uint32(node.Token.Pos.Line),
-				vm.TmpVarOperand(2),
-				vm.UnusedOperand(),
-				vm.CVOperand(uint32(symbol.Index)))
 		}
 
 		// Compile loop body
@@ -1436,7 +1814,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		// JMP back to FE_FETCH
-		c.EmitWithLine(vm.OpJmp, uint32(node.Token.Pos.Line),
+		c.EmitWithLine(vm.OpJmp, line,
 			vm.ConstOperand(uint32(startPos)),
 			vm.UnusedOperand(),
 			vm.UnusedOperand())
@@ -1445,11 +1823,11 @@ func (c *Compiler) Compile(node ast.Node) error {
 		endPos := c.CurrentPosition()
 
 		// Patch FE_FETCH jump
-		c.ChangeOperand(jmpEndPos, 1, vm.ConstOperand(uint32(endPos)))
+		c.ChangeOperand(jmpEndPos, 2, vm.ConstOperand(uint32(endPos)))
 
 		// FE_FREE: Clean up iterator
-		c.EmitWithLine(vm.OpFeFree, uint32(node.Token.Pos.Line),
-			vm.TmpVarOperand(1),
+		c.EmitWithLine(vm.OpFeFree, line,
+			iterator,
 			vm.UnusedOperand(),
 			vm.UnusedOperand())
 
@@ -1459,55 +1837,38 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 	// Break Statement
 	case *ast.BreakStatement:
-		if !c.InLoop() {
-			return fmt.Errorf("break statement outside of loop")
-		}
-
-		// Emit JMP with placeholder (will be patched by ExitLoop)
-		jmpPos := c.EmitWithLine(vm.OpJmp, uint32(node.Token.Pos.Line),
-			vm.UnusedOperand(),
-			vm.UnusedOperand(),
-			vm.UnusedOperand())
-
-		// Add to current loop's break jumps
-		loop := c.CurrentLoop()
-		loop.breakJumps = append(loop.breakJumps, jmpPos)
-		return nil
+		return c.compileJump(node.Token, node.Depth, false)
 
 	// Continue Statement
 	case *ast.ContinueStatement:
-		if !c.InLoop() {
-			return fmt.Errorf("continue statement outside of loop")
-		}
-
-		// Emit JMP with placeholder (will be patched by ExitLoop)
-		jmpPos := c.EmitWithLine(vm.OpJmp, uint32(node.Token.Pos.Line),
-			vm.UnusedOperand(),
-			vm.UnusedOperand(),
-			vm.UnusedOperand())
-
-		// Add to current loop's continue jumps
-		loop := c.CurrentLoop()
-		loop.continueJumps = append(loop.continueJumps, jmpPos)
-		return nil
+		return c.compileJump(node.Token, node.Depth, true)
 
 	// Switch Statement
 	case *ast.SwitchStatement:
-		// Compile switch subject
+		// Compile switch subject, kept out of temp 0 for the cases
 		if err := c.Compile(node.Subject); err != nil {
 			return err
 		}
-		subjectTemp := vm.TmpVarOperand(0)
+		subjectTemp := c.keepValue(uint32(node.Token.Pos.Line))
 
-		// Track case jump positions
-		caseJumps := []int{}
+		// Track case jump positions, indexed like node.Cases
+		caseJumps := make([]int, len(node.Cases))
 		var defaultCase *ast.SwitchCase
 
 		// Enter switch as a loop context (for break)
 		c.EnterLoop(c.CurrentPosition())
+		c.CurrentLoop().isSwitch = true
+
+		// With only integer or only non-numeric string labels, dispatch
+		// through a jump table; other subjects fall through to the
+		// IS_EQUAL chain below
+		table, tableOp := c.switchJumpTable(node.Cases)
+		if table != nil {
+			c.EmitWithLine(tableOp, uint32(node.Token.Pos.Line), subjectTemp, vm.ConstOperand(uint32(c.AddConstant(table))))
+		}
 
 		// Compile each case
-		for _, switchCase := range node.Cases {
+		for i, switchCase := range node.Cases {
 			if switchCase.Value == nil {
 				// Default case
 				defaultCase = switchCase
@@ -1518,20 +1879,19 @@ func (c *Compiler) Compile(node ast.Node) error {
 			if err := c.Compile(switchCase.Value); err != nil {
 				return err
 			}
-			caseValueTemp := vm.TmpVarOperand(1)
+			caseValueTemp := vm.TmpVarOperand(0)
 
 			// Compare subject == case value
 			c.EmitWithLine(vm.OpIsEqual, uint32(switchCase.Token.Pos.Line),
 				subjectTemp,
 				caseValueTemp,
-				vm.TmpVarOperand(2)) // Result in temp 2
+				vm.TmpVarOperand(0)) // Result in temp 0
 
 			// JMPNZ to case body if equal
-			jmpCase := c.EmitWithLine(vm.OpJmpNZ, uint32(switchCase.Token.Pos.Line),
-				vm.TmpVarOperand(2),
+			caseJumps[i] = c.EmitWithLine(vm.OpJmpNZ, uint32(switchCase.Token.Pos.Line),
+				vm.TmpVarOperand(0),
 				vm.UnusedOperand(),
 				vm.UnusedOperand())
-			caseJumps = append(caseJumps, jmpCase)
 		}
 
 		// If no match, jump to default or end
@@ -1548,11 +1908,14 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 			// Patch jump to this case
 			caseBodyPos := c.CurrentPosition()
-			c.ChangeOperand(caseJumps[i], 1, vm.ConstOperand(uint32(caseBodyPos)))
+			c.ChangeOperand(caseJumps[i], 2, vm.ConstOperand(uint32(caseBodyPos)))
+			if table != nil {
+				table.Retarget(-(i + 1), caseBodyPos)
+			}
 
 			// Compile case statements
 			for _, stmt := range switchCase.Body {
-				if err := c.Compile(stmt); err != nil {
+				if err := c.compileStatement(stmt); err != nil {
 					return err
 				}
 			}
@@ -1563,9 +1926,12 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if defaultCase != nil {
 			defaultBodyPos := c.CurrentPosition()
 			c.ChangeOperand(jmpDefault, 1, vm.ConstOperand(uint32(defaultBodyPos)))
+			if table != nil {
+				table.Default = defaultBodyPos
+			}
 
 			for _, stmt := range defaultCase.Body {
-				if err := c.Compile(stmt); err != nil {
+				if err := c.compileStatement(stmt); err != nil {
 					return err
 				}
 			}
@@ -1573,6 +1939,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 			// No default case, patch jump to end
 			endPos := c.CurrentPosition()
 			c.ChangeOperand(jmpDefault, 1, vm.ConstOperand(uint32(endPos)))
+			if table != nil {
+				table.Default = endPos
+			}
 		}
 
 		// End of switch
@@ -1582,81 +1951,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 	// Try-Catch-Finally Statement
 	case *ast.TryStatement:
-		// For now, implement simplified version
-		// Full exception handling requires VM support
-
-		// Use FAST_CALL for finally block if present
-		var fastCallPos int
-		if node.Finally != nil {
-			fastCallPos = c.EmitWithLine(vm.OpFastCall, uint32(node.Token.Pos.Line),
-				vm.UnusedOperand(),
-				vm.UnusedOperand(),
-				vm.UnusedOperand())
-		}
-
-		// Compile try block
-		if err := c.Compile(node.Body); err != nil {
-			return err
-		}
-
-		// JMP over catch blocks
-		jmpEndPos := c.EmitWithLine(vm.OpJmp, uint32(node.Token.Pos.Line),
-			vm.UnusedOperand(),
-			vm.UnusedOperand(),
-			vm.UnusedOperand())
-
-		// Compile catch clauses
-		for _, catchClause := range node.CatchClauses {
-			// CATCH opcode
-			c.EmitWithLine(vm.OpCatch, uint32(catchClause.Token.Pos.Line),
-				vm.UnusedOperand(),
-				vm.UnusedOperand(),
-				vm.TmpVarOperand(0)) // Exception in temp 0
-
-			// Assign exception to variable
-			if catchClause.Variable != nil {
-				symbol, ok := c.ResolveVariable(catchClause.Variable.Name)
-				if !ok {
-					symbol = c.DefineVariable(catchClause.Variable.Name)
-				}
-				c.EmitWithLine(vm.OpAssign, uint32(catchClause.Token.Pos.Line),
-					vm.TmpVarOperand(0),
-					vm.UnusedOperand(),
-					vm.CVOperand(uint32(symbol.Index)))
-			}
-
-			// Compile catch block
-			if err := c.Compile(catchClause.Body); err != nil {
-				return err
-			}
-
-			// JMP to finally/end
-			c.EmitWithLine(vm.OpJmp, uint32(catchClause.Token.Pos.Line),
-				vm.UnusedOperand(),
-				vm.UnusedOperand(),
-				vm.UnusedOperand())
-		}
-
-		// End position
-		endPos := c.CurrentPosition()
-		c.ChangeOperand(jmpEndPos, 1, vm.ConstOperand(uint32(endPos)))
-
-		// Compile finally block if present
-		if node.Finally != nil {
-			finallyPos := c.CurrentPosition()
-			c.ChangeOperand(fastCallPos, 1, vm.ConstOperand(uint32(finallyPos)))
-
-			if err := c.Compile(node.Finally); err != nil {
-				return err
-			}
-
-			// FAST_RET to return from finally
-			c.EmitWithLine(vm.OpFastRet, uint32(node.Token.Pos.Line),
-				vm.UnusedOperand(),
-				vm.UnusedOperand(),
-				vm.UnusedOperand())
-		}
-		return nil
+		return c.compileTry(node)
 
 	// Throw Statement
 	case *ast.ThrowStatement:
@@ -1676,16 +1971,42 @@ func (c *Compiler) Compile(node ast.Node) error {
 	// Function and Class Declarations
 	// ========================================
 
+	// Namespace Declaration
+	case *ast.NamespaceStatement:
+		return c.compileNamespace(node)
+
+	// Use Declaration (imports)
+	case *ast.UseStatement:
+		for _, item := range node.Items {
+			if err := c.namespace.AddUse(item.Kind, item.Name, item.Alias); err != nil {
+				return fmt.Errorf("line %d: %v", node.Token.Pos.Line, err)
+			}
+		}
+		return nil
+
 	// Function Declaration
 	case *ast.FunctionDeclaration:
-		// Store function name as constant
-		funcNameIdx := c.AddConstant(node.Name.Value)
+		// Store fully qualified function name as constant
+		funcName := types.InternString(c.namespace.prefix(node.Name.Value))
+		c.AddConstant(funcName)
+		c.declareSignature(funcName, node.Parameters)
 
-		// Remember function start position
-		funcStart := c.CurrentPosition()
+		params, err := c.parameterDefs(node.Parameters)
+		if err != nil {
+			return err
+		}
+		attrs, err := c.compileAttributes(node.Attributes)
+		if err != nil {
+			return err
+		}
+
+		// The body is compiled into an op array of its own
+		outerOpArray := c.enterOpArray()
 
 		// Enter new scope for function
 		c.EnterScope()
+		c.enterFunction(node.ReturnType)
+		outerScope := c.enterFunctionScope(funcName)
 
 		// Emit RECV opcodes for each parameter
 		for i, param := range node.Parameters {
@@ -1711,12 +2032,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 					vm.CVOperand(uint32(symbol.Index))) // Store in compiled variable
 			} else {
 				// RECV for required parameters
-				recvOp := vm.OpRecv
-				if param.ByRef {
-					recvOp = vm.OpSendRef // Use SEND_REF for by-reference parameters
-				}
-
-				c.EmitWithLine(recvOp, uint32(node.Token.Pos.Line),
+				c.EmitWithLine(vm.OpRecv, uint32(node.Token.Pos.Line),
 					vm.ConstOperand(uint32(i)),    // Parameter index
 					vm.UnusedOperand(),
 					vm.CVOperand(uint32(symbol.Index))) // Store in compiled variable
@@ -1729,405 +2045,802 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		// Add implicit return if function doesn't end with return
-		if !c.LastInstructionIs(vm.OpReturn) && !c.LastInstructionIs(vm.OpReturnByRef) {
-			// Return null
-			c.EmitWithLine(vm.OpReturn, uint32(node.Token.Pos.Line),
-				vm.UnusedOperand(),
-				vm.UnusedOperand(),
-				vm.UnusedOperand())
-		}
+		c.emitImplicitReturn(uint32(node.Token.Pos.Line))
 
 		// Exit function scope
+		numLocals := c.symbolTable.NumDefinitions()
+		variables := c.symbolTable.VariableNames()
+		c.exitFunction()
 		c.ExitScope()
-
-		// Function end position
-		funcEnd := c.CurrentPosition()
-
-		// DECLARE_FUNCTION to register the function
-		// Store function metadata: name index, start pos, end pos, num params
+		c.scope = outerScope
+
+		fn := &vm.CompiledFunction{
+			Name:        funcName,
+			NumLocals:   numLocals,
+			NumParams:   len(node.Parameters),
+			Variables:   variables,
+			Parameters:  params,
+			Attributes:  attrs,
+			ReturnByRef: node.ByRef,
+			DocComment:  node.DocComment,
+			StrictTypes: c.strictTypes,
+		}
+		if node.ReturnType != nil {
+			fn.ReturnType = node.ReturnType.String()
+		}
+		c.exitOpArray(outerOpArray, fn)
+
+		// DECLARE_FUNCTION to register the function, whose FunctionDecl
+		// constant holds the op array compiled above
+		decl := &vm.FunctionDecl{Function: fn}
 		c.EmitWithExtended(vm.OpDeclareFunction, uint32(node.Token.Pos.Line),
 			uint32(len(node.Parameters)), // Number of parameters
-			vm.ConstOperand(uint32(funcNameIdx)), // Function name
-			vm.ConstOperand(uint32(funcStart)),   // Function start position
-			vm.ConstOperand(uint32(funcEnd)))     // Function end position
+			vm.ConstOperand(uint32(c.AddConstant(decl))),
+			vm.UnusedOperand(),
+			vm.UnusedOperand())
 
 		return nil
 
 	// Class Declaration
 	case *ast.ClassDeclaration:
-		// Store class name as constant
-		classNameIdx := c.AddConstant(node.Name.Value)
-
-		// Store parent class name if extends
-		var parentIdx int
-		if node.Extends != nil {
-			parentIdx = c.AddConstant(node.Extends.Value)
-		}
-
-		// Remember class body start position
-		classStart := c.CurrentPosition()
-
-		// Compile class body (properties and methods)
-		for _, stmt := range node.Body {
-			switch decl := stmt.(type) {
-			case *ast.PropertyDeclaration:
-				// Compile property declarations
-				// Instance properties are initialized when the class is instantiated
-				// Static properties are initialized at class declaration time
-				for _, prop := range decl.Properties {
-					// Store property name as constant
-					propNameIdx := c.AddConstant(prop.Name.Name)
-
-					if decl.Static {
-						// Static property: initialize immediately
-						if prop.DefaultValue != nil {
-							// Compile default value
-							if err := c.Compile(prop.DefaultValue); err != nil {
-								return err
-							}
-
-							// ASSIGN_STATIC_PROP to initialize the static property
-							c.EmitWithLine(vm.OpAssignStaticProp, uint32(node.Token.Pos.Line),
-								vm.ConstOperand(uint32(classNameIdx)), // Class name
-								vm.ConstOperand(uint32(propNameIdx)),  // Property name
-								vm.TmpVarOperand(0))                   // Value in temp var 0
-						}
-						// If no default value, static property remains uninitialized (null)
-					} else {
-						// Instance property: just note existence
-						// Will be initialized when the class is instantiated
-						if prop.DefaultValue != nil {
-							if err := c.Compile(prop.DefaultValue); err != nil {
-								return err
-							}
-						}
-					}
-				}
+		return c.compileClassDeclaration(node)
 
-			case *ast.MethodDeclaration:
-				// Compile method similar to function but in class context
-				methodNameIdx := c.AddConstant(decl.Name.Value)
+	// Enum Declaration
+	case *ast.EnumDeclaration:
+		return c.compileEnum(node)
 
-				// Skip abstract methods (no body)
-				if decl.Abstract || decl.Body == nil {
-					continue
-				}
+	// Interface Declaration
+	case *ast.InterfaceDeclaration:
+		return c.compileInterfaceDeclaration(node)
 
-				methodStart := c.CurrentPosition()
+	// Trait Declaration
+	case *ast.TraitDeclaration:
+		return c.compileTraitDeclaration(node)
 
-				// Enter new scope for method
-				c.EnterScope()
+	// Method Declaration (when standalone, though usually part of class)
+	case *ast.MethodDeclaration:
+		// Methods are typically compiled as part of ClassDeclaration
+		// If encountered standalone, treat like a function
+		return fmt.Errorf("standalone method declaration not supported")
 
-				// Instance methods have implicit $this parameter
-				// Static methods do NOT have $this
-				if !decl.Static {
-					c.DefineVariable("this")
-				}
+	// Property Declaration (when standalone, though usually part of class)
+	case *ast.PropertyDeclaration:
+		// Properties are typically compiled as part of ClassDeclaration
+		// If encountered standalone, it's likely a parse error context
+		return fmt.Errorf("standalone property declaration not supported")
 
-				// Emit RECV opcodes for each parameter
-				for i, param := range decl.Parameters {
-					symbol := c.DefineVariable(param.Name.Name)
-
-					if param.Variadic {
-						c.EmitWithLine(vm.OpRecvVariadic, uint32(decl.Token.Pos.Line),
-							vm.ConstOperand(uint32(i)),
-							vm.UnusedOperand(),
-							vm.CVOperand(uint32(symbol.Index)))
-					} else if param.DefaultValue != nil {
-						if err := c.Compile(param.DefaultValue); err != nil {
-							return err
-						}
-						c.EmitWithLine(vm.OpRecvInit, uint32(decl.Token.Pos.Line),
-							vm.ConstOperand(uint32(i)),
-							vm.TmpVarOperand(0),
-							vm.CVOperand(uint32(symbol.Index)))
-					} else {
-						recvOp := vm.OpRecv
-						if param.ByRef {
-							recvOp = vm.OpSendRef
-						}
-						c.EmitWithLine(recvOp, uint32(decl.Token.Pos.Line),
-							vm.ConstOperand(uint32(i)),
-							vm.UnusedOperand(),
-							vm.CVOperand(uint32(symbol.Index)))
-					}
-				}
+	default:
+		return fmt.Errorf("compilation not yet implemented for node type: %T", node)
+	}
+}
 
-				// Compile method body
-				if err := c.Compile(decl.Body); err != nil {
-					return err
-				}
+// ========================================
+// Helper Methods
+// ========================================
 
-				// Add implicit return if method doesn't end with return
-				if !c.LastInstructionIs(vm.OpReturn) && !c.LastInstructionIs(vm.OpReturnByRef) {
-					c.EmitWithLine(vm.OpReturn, uint32(decl.Token.Pos.Line),
-						vm.UnusedOperand(),
-						vm.UnusedOperand(),
-						vm.UnusedOperand())
-				}
+// Instructions returns the current instruction list
+func (c *Compiler) Instructions() vm.Instructions {
+	return c.instructions
+}
 
-				// Exit method scope
-				c.ExitScope()
+// Reset resets the compiler state for a new compilation
+func (c *Compiler) Reset() {
+	c.instructions = vm.Instructions{}
+	c.constants = []interface{}{}
+	c.constantMap = make(map[interface{}]int)
+	c.lastInstruction = EmittedInstruction{}
+	c.previousInstruction = EmittedInstruction{}
+	c.loopStack = []*LoopContext{}
+	c.tryCatch = nil
+	c.tryStack = nil
+	c.temps = 0
+	c.namespace = NewNamespaceContext("")
+	c.functions = nil
+	c.InitSymbolTable()
+}
 
-				methodEnd := c.CurrentPosition()
+// ========================================
+// Loop Context Management
+// ========================================
 
-				// Store method metadata
-				_ = methodNameIdx
-				_ = methodStart
-				_ = methodEnd
+// EnterLoop pushes a new loop context onto the stack
+func (c *Compiler) EnterLoop(startPos int) {
+	c.loopStack = append(c.loopStack, &LoopContext{
+		startPos:      startPos,
+		breakJumps:    []int{},
+		continueJumps: []int{},
+		tryDepth:      len(c.tryStack),
+	})
+}
 
-			default:
-				// Other class body elements (constants, trait uses, etc.)
-				if err := c.Compile(stmt); err != nil {
-					return err
-				}
-			}
-		}
+// ExitLoop pops a loop context and patches all break/continue jumps
+func (c *Compiler) ExitLoop(endPos int) {
+	if len(c.loopStack) == 0 {
+		return
+	}
 
-		// Class end position
-		classEnd := c.CurrentPosition()
+	loop := c.loopStack[len(c.loopStack)-1]
+	c.loopStack = c.loopStack[:len(c.loopStack)-1]
 
-		// DECLARE_CLASS to register the class
-		if node.Extends != nil {
-			// Class with parent - use extended value for parent index
-			c.EmitWithExtended(vm.OpDeclareClass, uint32(node.Token.Pos.Line),
-				uint32(parentIdx), // Parent class name index
-				vm.ConstOperand(uint32(classNameIdx)), // Class name
-				vm.ConstOperand(uint32(classStart)),   // Class start position
-				vm.ConstOperand(uint32(classEnd)))     // Class end position
-		} else {
-			// Class without parent
-			c.EmitWithExtended(vm.OpDeclareClass, uint32(node.Token.Pos.Line),
-				0, // No parent
-				vm.ConstOperand(uint32(classNameIdx)), // Class name
-				vm.ConstOperand(uint32(classStart)),   // Class start position
-				vm.ConstOperand(uint32(classEnd)))     // Class end position
-		}
+	// Patch all break jumps to jump to end
+	for _, pos := range loop.breakJumps {
+		c.ChangeOperand(pos, 1, vm.ConstOperand(uint32(endPos)))
+	}
 
+	// Patch all continue jumps to jump to start
+	for _, pos := range loop.continueJumps {
+		c.ChangeOperand(pos, 1, vm.ConstOperand(uint32(loop.startPos)))
+	}
+}
+
+// CurrentLoop returns the current loop context (nil if not in a loop)
+func (c *Compiler) CurrentLoop() *LoopContext {
+	if len(c.loopStack) == 0 {
 		return nil
+	}
+	return c.loopStack[len(c.loopStack)-1]
+}
 
-	// Interface Declaration
-	case *ast.InterfaceDeclaration:
-		// Store interface name as constant
-		interfaceNameIdx := c.AddConstant(node.Name.Value)
+// InLoop returns true if currently inside a loop
+func (c *Compiler) InLoop() bool {
+	return len(c.loopStack) > 0
+}
+
+// ========================================
+// Operand Helpers
+// ========================================
+
+// compileOperand compiles an operand whose value must survive the
+// compilation of the operands after it. Literals are constant operands and
+// the variables of a function its compiled variables; other values are
+// moved out of temp 0 into a temporary of their own.
+func (c *Compiler) compileOperand(expr ast.Expr) (vm.Operand, error) {
+	if operand, ok := c.directOperand(expr); ok {
+		return operand, nil
+	}
+	if err := c.Compile(expr); err != nil {
+		return vm.Operand{}, err
+	}
+	return c.keepValue(uint32(expr.Pos().Line)), nil
+}
+
+// compileLastOperand compiles the last operand of an instruction, which
+// nothing is compiled after: literals and the variables of a function are
+// used directly, other values are left in temp 0
+func (c *Compiler) compileLastOperand(expr ast.Expr) (vm.Operand, error) {
+	if operand, ok := c.directOperand(expr); ok {
+		return operand, nil
+	}
+	if err := c.Compile(expr); err != nil {
+		return vm.Operand{}, err
+	}
+	return vm.TmpVarOperand(0), nil
+}
 
-		// Store parent interface names if extends
-		parentIndices := []int{}
-		for _, parent := range node.Extends {
-			parentIdx := c.AddConstant(parent.Value)
-			parentIndices = append(parentIndices, parentIdx)
+// containerOperand compiles the array or object an element or property is
+// read from. A variable is used as the compiled variable, in the script
+// too: moved into a temporary, an array would be shared with the
+// temporary, and the next write to the variable would copy it.
+func (c *Compiler) containerOperand(expr ast.Expr) (vm.Operand, error) {
+	if variable, ok := expr.(*ast.Variable); ok && variable.Name != "this" && variable.Name != "GLOBALS" {
+		return c.variableOperand(variable), nil
+	}
+	return c.compileOperand(expr)
+}
+
+// directOperand returns the operand reading an expression without
+// compiling it: a constant for a literal, or the compiled variable of a
+// local variable. Global variables are fetched, which warns when they are
+// undefined.
+func (c *Compiler) directOperand(expr ast.Expr) (vm.Operand, bool) {
+	if value, ok := getConstantValue(expr); ok {
+		return vm.ConstOperand(uint32(c.AddConstant(value))), true
+	}
+	if variable, ok := expr.(*ast.Variable); ok && variable.Name != "this" {
+		if symbol, ok := c.symbolTable.store[variable.Name]; ok && symbol.Scope == LocalScope {
+			return vm.CVOperand(uint32(symbol.Index)), true
 		}
+	}
+	return vm.Operand{}, false
+}
 
-		// For now, interfaces are compile-time metadata
-		// We'll just note their existence
-		// TODO: Implement runtime interface checking and type validation
+// compileLogical compiles && and || (and, or), leaving a bool in temp 0.
+// The right operand is skipped when the left one decides the result: jump
+// is JMPZ for &&, JMPNZ for ||.
+func (c *Compiler) compileLogical(node *ast.InfixExpression, jump vm.Opcode) error {
+	line := uint32(node.Token.Pos.Line)
+	if err := c.Compile(node.Left); err != nil {
+		return err
+	}
+	c.EmitWithLine(vm.OpBool, line, vm.TmpVarOperand(0), vm.UnusedOperand(), vm.TmpVarOperand(0))
+	skip := c.EmitWithLine(jump, line, vm.TmpVarOperand(0), vm.UnusedOperand(), vm.UnusedOperand())
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+	c.EmitWithLine(vm.OpBool, line, vm.TmpVarOperand(0), vm.UnusedOperand(), vm.TmpVarOperand(0))
+	c.ChangeOperand(skip, 2, vm.ConstOperand(uint32(c.CurrentPosition())))
+	return nil
+}
 
-		// Store method signatures for interface validation
-		for _, methodSig := range node.Body {
-			_ = methodSig // Store method signature metadata
-			methodNameIdx := c.AddConstant(methodSig.Name.Value)
-			_ = methodNameIdx
+// ========================================
+// Compound Assignment Helpers
+// ========================================
+
+// compoundOperators maps compound assignment operators to the opcode of
+// their binary operator, which ASSIGN_OP, ASSIGN_DIM_OP and ASSIGN_OBJ_OP
+// carry in their extended value
+var compoundOperators = map[string]vm.Opcode{
+	"+=":  vm.OpAdd,
+	"-=":  vm.OpSub,
+	"*=":  vm.OpMul,
+	"/=":  vm.OpDiv,
+	"%=":  vm.OpMod,
+	"**=": vm.OpPow,
+	".=":  vm.OpConcat,
+	"&=":  vm.OpBWAnd,
+	"|=":  vm.OpBWOr,
+	"^=":  vm.OpBWXor,
+	"<<=": vm.OpSL,
+	">>=": vm.OpSR,
+}
+
+// incDecOpcodes maps ++ and -- (prefix and postfix) to the opcodes for
+// variables and for object properties
+var incDecOpcodes = map[string][2]vm.Opcode{
+	"++":          {vm.OpPreInc, vm.OpPreIncObj},
+	"--":          {vm.OpPreDec, vm.OpPreDecObj},
+	"++(postfix)": {vm.OpPostInc, vm.OpPostIncObj},
+	"--(postfix)": {vm.OpPostDec, vm.OpPostDecObj},
+}
+
+// compileCompoundAssignment compiles $var op= expr, $arr[key] op= expr and
+// $obj->prop op= expr. The assigned value is left in temp 0.
+func (c *Compiler) compileCompoundAssignment(node *ast.AssignmentExpression, opcode vm.Opcode) error {
+	line := uint32(node.Token.Pos.Line)
+
+	switch left := node.Left.(type) {
+	case *ast.Variable:
+		if err := c.Compile(node.Right); err != nil {
+			return err
 		}
+		c.EmitWithExtended(vm.OpAssignOp, line, uint32(opcode), c.variableOperand(left), vm.TmpVarOperand(0), vm.TmpVarOperand(0))
+		return nil
 
-		// Placeholder - interfaces don't generate runtime code
-		// They're used for compile-time type checking
-		_ = interfaceNameIdx
-		_ = parentIndices
+	case *ast.IndexExpression:
+		if left.Index == nil {
+			return fmt.Errorf("compound assignment to %s is not supported", left.String())
+		}
+		array, fetches, ok, err := c.compileWriteContainer(left.Left)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("compound assignment to %s is not supported", left.String())
+		}
+		key, err := c.compileOperand(left.Index)
+		if err != nil {
+			return err
+		}
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+		c.emitContainerFetches(fetches)
+		c.EmitWithExtended(vm.OpAssignDimOp, line, uint32(opcode), array, key, vm.TmpVarOperand(0))
+		c.EmitWithLine(vm.OpFetchDimR, line, array, key, vm.TmpVarOperand(0))
+		return nil
 
+	case *ast.PropertyExpression:
+		object, property, err := c.compilePropertyTarget(left)
+		if err != nil {
+			return err
+		}
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+		c.EmitWithExtended(vm.OpAssignObjOp, line, uint32(opcode), object, property, vm.TmpVarOperand(0))
+		c.EmitWithLine(vm.OpFetchObjR, line, object, property, vm.TmpVarOperand(0))
 		return nil
+	}
 
-	// Trait Declaration
-	case *ast.TraitDeclaration:
-		// Store trait name as constant
-		traitNameIdx := c.AddConstant(node.Name.Value)
-
-		// Traits are similar to classes but cannot be instantiated
-		// They provide methods that can be included in classes
-
-		// Remember trait body start position
-		traitStart := c.CurrentPosition()
-
-		// Compile trait body (properties and methods)
-		for _, stmt := range node.Body {
-			switch decl := stmt.(type) {
-			case *ast.PropertyDeclaration:
-				// Trait properties
-				for _, prop := range decl.Properties {
-					propNameIdx := c.AddConstant(prop.Name.Name)
-					_ = propNameIdx
-
-					if prop.DefaultValue != nil {
-						if err := c.Compile(prop.DefaultValue); err != nil {
-							return err
-						}
-					}
-				}
+	return fmt.Errorf("compound assignment to %s is not supported", node.Left.String())
+}
 
-			case *ast.MethodDeclaration:
-				// Trait methods - compile similar to class methods
-				methodNameIdx := c.AddConstant(decl.Name.Value)
+// compileIncDec compiles ++ and -- on variables and object properties,
+// leaving the value of the expression in temp 0
+func (c *Compiler) compileIncDec(node *ast.PrefixExpression) error {
+	line := uint32(node.Token.Pos.Line)
+	opcodes := incDecOpcodes[node.Operator]
 
-				if decl.Abstract || decl.Body == nil {
-					continue
-				}
+	switch operand := node.Right.(type) {
+	case *ast.Variable:
+		c.EmitWithLine(opcodes[0], line, c.variableOperand(operand), vm.UnusedOperand(), vm.TmpVarOperand(0))
+		return nil
 
-				methodStart := c.CurrentPosition()
+	case *ast.PropertyExpression:
+		object, property, err := c.compilePropertyTarget(operand)
+		if err != nil {
+			return err
+		}
+		c.EmitWithLine(opcodes[1], line, object, property, vm.TmpVarOperand(0))
+		return nil
+	}
 
-				c.EnterScope()
+	return fmt.Errorf("cannot apply %s to %s", strings.TrimSuffix(node.Operator, "(postfix)"), node.Right.String())
+}
 
-				// Trait methods can access $this when used in a class
-				if !decl.Static {
-					c.DefineVariable("this")
-				}
+// compileSilence compiles the @ operator: the operand is evaluated between
+// BEGIN_SILENCE and END_SILENCE, which suppress and restore error reporting
+func (c *Compiler) compileSilence(node *ast.PrefixExpression) error {
+	line := uint32(node.Token.Pos.Line)
+	c.EmitWithLine(vm.OpBeginSilence, line, vm.UnusedOperand(), vm.UnusedOperand(), vm.UnusedOperand())
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+	c.EmitWithLine(vm.OpEndSilence, line, vm.UnusedOperand(), vm.UnusedOperand(), vm.UnusedOperand())
+	return nil
+}
 
-				// Emit RECV opcodes for parameters
-				for i, param := range decl.Parameters {
-					symbol := c.DefineVariable(param.Name.Name)
-
-					if param.Variadic {
-						c.EmitWithLine(vm.OpRecvVariadic, uint32(decl.Token.Pos.Line),
-							vm.ConstOperand(uint32(i)),
-							vm.UnusedOperand(),
-							vm.CVOperand(uint32(symbol.Index)))
-					} else if param.DefaultValue != nil {
-						if err := c.Compile(param.DefaultValue); err != nil {
-							return err
-						}
-						c.EmitWithLine(vm.OpRecvInit, uint32(decl.Token.Pos.Line),
-							vm.ConstOperand(uint32(i)),
-							vm.TmpVarOperand(0),
-							vm.CVOperand(uint32(symbol.Index)))
-					} else {
-						recvOp := vm.OpRecv
-						if param.ByRef {
-							recvOp = vm.OpSendRef
-						}
-						c.EmitWithLine(recvOp, uint32(decl.Token.Pos.Line),
-							vm.ConstOperand(uint32(i)),
-							vm.UnusedOperand(),
-							vm.CVOperand(uint32(symbol.Index)))
-					}
-				}
+// variableOperand returns the compiled variable operand of a variable,
+// defining it on first use
+func (c *Compiler) variableOperand(variable *ast.Variable) vm.Operand {
+	symbol := c.variableSymbol(variable.Name)
+	return vm.CVOperand(uint32(symbol.Index))
+}
 
-				// Compile method body
-				if err := c.Compile(decl.Body); err != nil {
-					return err
-				}
+// compilePropertyTarget compiles the object and property name of a
+// property that is written to, keeping them out of the temps the
+// assigned value is compiled into
+func (c *Compiler) compilePropertyTarget(node *ast.PropertyExpression) (vm.Operand, vm.Operand, error) {
+	object, err := c.compileOperand(node.Object)
+	if err != nil {
+		return vm.Operand{}, vm.Operand{}, err
+	}
 
-				// Add implicit return if method doesn't end with return
-				if !c.LastInstructionIs(vm.OpReturn) && !c.LastInstructionIs(vm.OpReturnByRef) {
-					c.EmitWithLine(vm.OpReturn, uint32(decl.Token.Pos.Line),
-						vm.UnusedOperand(),
-						vm.UnusedOperand(),
-						vm.UnusedOperand())
-				}
+	if name, ok := node.Property.(*ast.Identifier); ok {
+		return object, vm.ConstOperand(uint32(c.AddConstant(name.Value))), nil
+	}
+	property, err := c.compileOperand(node.Property)
+	if err != nil {
+		return vm.Operand{}, vm.Operand{}, err
+	}
+	return object, property, nil
+}
 
-				c.ExitScope()
+// compileDimAssignment compiles $var[key] = expr, and the assignments to
+// the elements of elements and properties ($a[k1][k2] = expr,
+// $obj->prop[key] = expr). The keys are evaluated before the value;
+// ASSIGN_DIM writes an array element or, on a string, a single byte. The
+// assigned value is left in temp 0.
+func (c *Compiler) compileDimAssignment(left *ast.IndexExpression, right ast.Expr, line uint32) error {
+	if left.Index == nil {
+		return fmt.Errorf("assignment to %s is not supported", left.String())
+	}
+	container, fetches, ok, err := c.compileWriteContainer(left.Left)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("assignment to %s is not supported", left.String())
+	}
+	key, err := c.compileOperand(left.Index)
+	if err != nil {
+		return err
+	}
+	if err := c.Compile(right); err != nil {
+		return err
+	}
+	c.emitContainerFetches(fetches)
+	c.EmitWithLine(vm.OpAssignDim, line, container, key, vm.TmpVarOperand(0))
+	return nil
+}
 
-				methodEnd := c.CurrentPosition()
+// containerFetch is a FETCH_DIM_W or FETCH_OBJ_W of an array an element
+// is written into
+type containerFetch struct {
+	opcode    vm.Opcode
+	line      uint32
+	container vm.Operand
+	key       vm.Operand
+	result    vm.Operand
+}
 
-				_ = methodNameIdx
-				_ = methodStart
-				_ = methodEnd
+// compileWriteContainer compiles the array an element is written into: a
+// variable, or an element or property of another container, which is
+// fetched for writing by the returned instructions. Like PHP, the caller
+// emits these after computing the assigned value, so the value sees the
+// containers as they were. ok is false for expressions that cannot be
+// written into.
+func (c *Compiler) compileWriteContainer(expr ast.Expr) (vm.Operand, []containerFetch, bool, error) {
+	switch node := expr.(type) {
+	case *ast.Variable:
+		return c.variableOperand(node), nil, true, nil
 
-			default:
-				if err := c.Compile(stmt); err != nil {
-					return err
-				}
+	case *ast.IndexExpression:
+		if node.Index == nil {
+			return vm.Operand{}, nil, false, nil
+		}
+		container, fetches, ok, err := c.compileWriteContainer(node.Left)
+		if err != nil || !ok {
+			return vm.Operand{}, nil, ok, err
+		}
+		key, err := c.compileOperand(node.Index)
+		if err != nil {
+			return vm.Operand{}, nil, false, err
+		}
+		element := c.newTemp()
+		fetch := containerFetch{vm.OpFetchDimW, uint32(node.Token.Pos.Line), container, key, element}
+		return element, append(fetches, fetch), true, nil
+
+	case *ast.PropertyExpression:
+		object, property, err := c.compilePropertyTarget(node)
+		if err != nil {
+			return vm.Operand{}, nil, false, err
+		}
+		value := c.newTemp()
+		fetch := containerFetch{vm.OpFetchObjW, uint32(node.Token.Pos.Line), object, property, value}
+		return value, []containerFetch{fetch}, true, nil
+	}
+	return vm.Operand{}, nil, false, nil
+}
+
+// emitContainerFetches emits the fetches of the containers an element is
+// written into, outermost first
+func (c *Compiler) emitContainerFetches(fetches []containerFetch) {
+	for _, fetch := range fetches {
+		c.EmitWithLine(fetch.opcode, fetch.line, fetch.container, fetch.key, fetch.result)
+	}
+}
+
+// ========================================
+// List Assignment Helpers
+// ========================================
+
+// compileListAssignment compiles list(...) = expr. A variable on the right
+// is destructured in place, which by-reference elements require, unless
+// the list assigns to it; anything else is evaluated into a temp first.
+// The value of the right side is left in temp 0.
+func (c *Compiler) compileListAssignment(list *ast.ListExpression, right ast.Expr) error {
+	line := uint32(list.Token.Pos.Line)
+
+	if variable, ok := right.(*ast.Variable); ok && !listAssignsTo(list, variable.Name) {
+		if err := c.compileList(list, c.variableOperand(variable), true, 0); err != nil {
+			return err
+		}
+		return c.Compile(right)
+	}
+
+	if listHasReference(list) {
+		return fmt.Errorf("cannot assign reference to non referenceable value")
+	}
+	if err := c.Compile(right); err != nil {
+		return err
+	}
+	source := c.keepValue(line)
+	if err := c.compileList(list, source, false, 0); err != nil {
+		return err
+	}
+	c.EmitWithLine(vm.OpQMAssign, line, source, vm.UnusedOperand(), vm.TmpVarOperand(0))
+	return nil
+}
+
+// compileList assigns the elements of the array in source to the targets
+// of a list pattern, left to right. Elements without a key take the
+// positions 0, 1, 2, ... (skipped elements included). Each element is
+// fetched with FETCH_LIST_R, or with FETCH_LIST_W as a reference when it
+// is assigned by reference or contains such elements, which needs a
+// writable source.
+func (c *Compiler) compileList(list *ast.ListExpression, source vm.Operand, writable bool, depth int) error {
+	line := uint32(list.Token.Pos.Line)
+	element := c.newTemp()
+
+	keyed := len(list.Elements) > 0 && list.Elements[0].Key != nil
+	for i, elem := range list.Elements {
+		if elem.Value == nil {
+			if keyed {
+				return fmt.Errorf("cannot use empty array entries in keyed array assignment")
 			}
+			continue
+		}
+		if (elem.Key != nil) != keyed {
+			return fmt.Errorf("cannot mix keyed and unkeyed array entries in assignments")
 		}
 
-		traitEnd := c.CurrentPosition()
+		key, err := c.compileListKey(elem.Key, i)
+		if err != nil {
+			return err
+		}
+
+		nested, isList := elem.Value.(*ast.ListExpression)
+		byRef := elem.ByRef || (isList && listHasReference(nested))
+		if byRef && !writable {
+			return fmt.Errorf("cannot assign reference to non referenceable value")
+		}
+		fetch := vm.OpFetchListR
+		if byRef {
+			fetch = vm.OpFetchListW
+		}
+		c.EmitWithLine(fetch, line, source, key, element)
 
-		// TODO: Implement DECLARE_TRAIT opcode for runtime trait registration
-		// For now, traits are compile-time metadata
-		_ = traitNameIdx
-		_ = traitStart
-		_ = traitEnd
+		switch target := elem.Value.(type) {
+		case *ast.ListExpression:
+			if err := c.compileList(target, element, byRef, depth+1); err != nil {
+				return err
+			}
+		case *ast.Variable:
+			if elem.ByRef {
+				c.EmitWithLine(vm.OpAssignRef, line, c.variableOperand(target), element, vm.UnusedOperand())
+			} else {
+				c.EmitWithLine(vm.OpAssign, line, vm.UnusedOperand(), element, c.variableOperand(target))
+			}
+		default:
+			if elem.ByRef {
+				return fmt.Errorf("list() assignment by reference to %s is not supported", elem.Value.String())
+			}
+			if err := c.compileListTarget(elem.Value, element, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
+// compileListTarget assigns an element fetched by a list pattern to an
+// array element, a property, a static property or a variable variable.
+// Like any write target, it is evaluated after the element is fetched.
+func (c *Compiler) compileListTarget(target ast.Expr, element vm.Operand, line uint32) error {
+	if hasNullsafe(target) {
+		return fmt.Errorf("cannot use nullsafe operator in write context")
+	}
+	switch target := target.(type) {
+	case *ast.IndexExpression:
+		if target.Index == nil {
+			break
+		}
+		container, fetches, ok, err := c.compileWriteContainer(target.Left)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		key, err := c.compileOperand(target.Index)
+		if err != nil {
+			return err
+		}
+		c.emitContainerFetches(fetches)
+		c.EmitWithLine(vm.OpAssignDim, line, container, key, element)
 		return nil
 
-	// Method Declaration (when standalone, though usually part of class)
-	case *ast.MethodDeclaration:
-		// Methods are typically compiled as part of ClassDeclaration
-		// If encountered standalone, treat like a function
-		return fmt.Errorf("standalone method declaration not supported")
+	case *ast.PropertyExpression:
+		object, property, err := c.compilePropertyTarget(target)
+		if err != nil {
+			return err
+		}
+		c.EmitWithLine(vm.OpAssignObj, line, object, property, element)
+		return nil
 
-	// Property Declaration (when standalone, though usually part of class)
-	case *ast.PropertyDeclaration:
-		// Properties are typically compiled as part of ClassDeclaration
-		// If encountered standalone, it's likely a parse error context
-		return fmt.Errorf("standalone property declaration not supported")
+	case *ast.StaticPropertyExpression:
+		c.EmitWithLine(vm.OpQMAssign, line, element, vm.UnusedOperand(), vm.TmpVarOperand(0))
+		return c.compileStaticPropertyAssignment(target, line)
 
-	default:
-		return fmt.Errorf("compilation not yet implemented for node type: %T", node)
+	case *ast.VariableVariable:
+		c.EmitWithLine(vm.OpQMAssign, line, element, vm.UnusedOperand(), vm.TmpVarOperand(0))
+		return c.compileVariableVariableAssignment(target, line)
 	}
+	return fmt.Errorf("cannot assign to %s in list()", target.String())
 }
 
-// ========================================
-// Helper Methods
-// ========================================
+// compileListKey returns the operand of the key of a list element: the
+// position for elements without a key, a constant for literal keys, and
+// otherwise the key computed into a temporary
+func (c *Compiler) compileListKey(key ast.Expr, position int) (vm.Operand, error) {
+	if key == nil {
+		return vm.ConstOperand(uint32(c.AddConstant(int64(position)))), nil
+	}
+	return c.compileOperand(key)
+}
 
-// Instructions returns the current instruction list
-func (c *Compiler) Instructions() vm.Instructions {
-	return c.instructions
+// listHasReference reports whether a list pattern assigns by reference
+func listHasReference(list *ast.ListExpression) bool {
+	for _, elem := range list.Elements {
+		if elem.ByRef {
+			return true
+		}
+		if nested, ok := elem.Value.(*ast.ListExpression); ok && listHasReference(nested) {
+			return true
+		}
+	}
+	return false
 }
 
-// Reset resets the compiler state for a new compilation
-func (c *Compiler) Reset() {
-	c.instructions = vm.Instructions{}
-	c.constants = []interface{}{}
-	c.constantMap = make(map[interface{}]int)
-	c.lastInstruction = EmittedInstruction{}
-	c.previousInstruction = EmittedInstruction{}
-	c.loopStack = []*LoopContext{}
-	c.InitSymbolTable()
+// listAssignsTo reports whether a list pattern assigns to the named
+// variable, in which case the variable must be copied before it is
+// destructured
+func listAssignsTo(list *ast.ListExpression, name string) bool {
+	for _, elem := range list.Elements {
+		switch target := elem.Value.(type) {
+		case *ast.Variable:
+			if target.Name == name {
+				return true
+			}
+		case *ast.ListExpression:
+			if listAssignsTo(target, name) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // ========================================
-// Loop Context Management
+// Match and Switch Helpers
 // ========================================
 
-// EnterLoop pushes a new loop context onto the stack
-func (c *Compiler) EnterLoop(startPos int) {
-	c.loopStack = append(c.loopStack, &LoopContext{
-		startPos:      startPos,
-		breakJumps:    []int{},
-		continueJumps: []int{},
-	})
-}
+// Minimum number of labels before a jump table pays off (as in php-src)
+const (
+	minLongJumpTableCases   = 5
+	minStringJumpTableCases = 2
+)
 
-// ExitLoop pops a loop context and patches all break/continue jumps
-func (c *Compiler) ExitLoop(endPos int) {
-	if len(c.loopStack) == 0 {
-		return
+// compileMatch compiles a match expression. Arms are tried in order with
+// strict comparison (CASE_STRICT) and never fall through; when no arm
+// matches and there is no default, MATCH_ERROR throws
+// UnhandledMatchError. If every condition is an integer literal, or every
+// condition a string literal, the arms are dispatched through a MATCH
+// jump table instead. The result is left in temp 0.
+func (c *Compiler) compileMatch(node *ast.MatchExpression) error {
+	line := uint32(node.Token.Pos.Line)
+
+	if err := c.Compile(node.Subject); err != nil {
+		return err
+	}
+	// Keep the subject out of temp 0, where the conditions are compiled
+	subject := c.keepValue(line)
+
+	// Jumps to each arm's body, patched once the bodies are emitted
+	armJumps := make([][]int, len(node.Arms))
+	var jmpDefault int
+
+	table := c.matchJumpTable(node.Arms)
+	if table != nil {
+		c.EmitWithLine(vm.OpMatch, line, subject, vm.ConstOperand(uint32(c.AddConstant(table))))
+	} else {
+		for i, arm := range node.Arms {
+			if arm.IsDefault {
+				continue
+			}
+			for _, condition := range arm.Conditions {
+				if err := c.Compile(condition); err != nil {
+					return err
+				}
+				c.EmitWithLine(vm.OpCaseStrict, line, subject, vm.TmpVarOperand(0), vm.TmpVarOperand(0))
+				jmp := c.EmitWithLine(vm.OpJmpNZ, line, vm.TmpVarOperand(0), vm.UnusedOperand(), vm.UnusedOperand())
+				armJumps[i] = append(armJumps[i], jmp)
+			}
+		}
+		jmpDefault = c.EmitWithLine(vm.OpJmp, line, vm.UnusedOperand(), vm.UnusedOperand(), vm.UnusedOperand())
 	}
 
-	loop := c.loopStack[len(c.loopStack)-1]
-	c.loopStack = c.loopStack[:len(c.loopStack)-1]
+	// Compile the arm bodies; each jumps to the end (no fall-through)
+	endJumps := []int{}
+	defaultPos := -1
+	for i, arm := range node.Arms {
+		bodyPos := c.CurrentPosition()
+		if arm.IsDefault {
+			defaultPos = bodyPos
+		}
+		for _, jmp := range armJumps[i] {
+			c.ChangeOperand(jmp, 2, vm.ConstOperand(uint32(bodyPos)))
+		}
+		if table != nil {
+			table.Retarget(-(i + 1), bodyPos)
+		}
 
-	// Patch all break jumps to jump to end
-	for _, pos := range loop.breakJumps {
-		c.ChangeOperand(pos, 1, vm.ConstOperand(uint32(endPos)))
+		if err := c.Compile(arm.Body); err != nil {
+			return err
+		}
+		endJumps = append(endJumps, c.EmitWithLine(vm.OpJmp, line, vm.UnusedOperand(), vm.UnusedOperand(), vm.UnusedOperand()))
 	}
 
-	// Patch all continue jumps to jump to start
-	for _, pos := range loop.continueJumps {
-		c.ChangeOperand(pos, 1, vm.ConstOperand(uint32(loop.startPos)))
+	if defaultPos < 0 {
+		defaultPos = c.CurrentPosition()
+		c.EmitWithLine(vm.OpMatchError, line, subject)
+	}
+	if table != nil {
+		table.Default = defaultPos
+	} else {
+		c.ChangeOperand(jmpDefault, 1, vm.ConstOperand(uint32(defaultPos)))
+	}
+
+	endPos := c.CurrentPosition()
+	for _, jmp := range endJumps {
+		c.ChangeOperand(jmp, 1, vm.ConstOperand(uint32(endPos)))
 	}
+	return nil
 }
 
-// CurrentLoop returns the current loop context (nil if not in a loop)
-func (c *Compiler) CurrentLoop() *LoopContext {
-	if len(c.loopStack) == 0 {
+// matchJumpTable builds a jump table for match arms whose conditions are
+// all integer literals or all string literals. Targets are placeholders
+// -(arm index + 1) until the bodies are emitted.
+func (c *Compiler) matchJumpTable(arms []*ast.MatchArm) *vm.JumpTable {
+	var conditions []ast.Expr
+	targets := []int{}
+	for i, arm := range arms {
+		if arm.IsDefault {
+			continue
+		}
+		for _, condition := range arm.Conditions {
+			conditions = append(conditions, condition)
+			targets = append(targets, -(i + 1))
+		}
+	}
+	return buildJumpTable(conditions, targets, false)
+}
+
+// switchJumpTable builds a jump table for switch cases, returning the
+// dispatch opcode to use. String labels must be non-numeric, as numeric
+// strings compare loosely with other numeric strings.
+func (c *Compiler) switchJumpTable(cases []*ast.SwitchCase) (*vm.JumpTable, vm.Opcode) {
+	var conditions []ast.Expr
+	targets := []int{}
+	for i, switchCase := range cases {
+		if switchCase.Value != nil {
+			conditions = append(conditions, switchCase.Value)
+			targets = append(targets, -(i + 1))
+		}
+	}
+	table := buildJumpTable(conditions, targets, true)
+	if table == nil {
+		return nil, vm.OpNop
+	}
+	if len(table.Longs) > 0 {
+		return table, vm.OpSwitchLong
+	}
+	return table, vm.OpSwitchString
+}
+
+// buildJumpTable returns a table when all conditions are literals of the
+// same type (integer or string) and there are enough of them
+func buildJumpTable(conditions []ast.Expr, targets []int, loose bool) *vm.JumpTable {
+	if len(conditions) == 0 {
 		return nil
 	}
-	return c.loopStack[len(c.loopStack)-1]
+
+	table := vm.NewJumpTable()
+	switch conditions[0].(type) {
+	case *ast.IntegerLiteral:
+		if len(conditions) < minLongJumpTableCases {
+			return nil
+		}
+		for i, condition := range conditions {
+			literal, ok := condition.(*ast.IntegerLiteral)
+			if !ok {
+				return nil
+			}
+			table.Add(literal.Value, targets[i])
+		}
+	case *ast.StringLiteral:
+		if len(conditions) < minStringJumpTableCases {
+			return nil
+		}
+		for i, condition := range conditions {
+			literal, ok := condition.(*ast.StringLiteral)
+			if !ok || (loose && isNumericLiteral(literal.Value)) {
+				return nil
+			}
+			table.Add(literal.Value, targets[i])
+		}
+	default:
+		return nil
+	}
+	return table
 }
 
-// InLoop returns true if currently inside a loop
-func (c *Compiler) InLoop() bool {
-	return len(c.loopStack) > 0
+// isNumericLiteral reports whether a string label is numeric (allowing
+// surrounding whitespace); such labels are left to IS_EQUAL
+func isNumericLiteral(s string) bool {
+	_, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return err == nil || errors.Is(err, strconv.ErrRange)
 }
 
 // ========================================
@@ -2171,29 +2884,28 @@ func foldConstantBinaryOp(left, right interface{}, operator string) (interface{}
 	if leftIsInt && rightIsInt {
 		switch operator {
 		case "+":
-			return leftInt + rightInt, true
+			return foldedNumber(types.Add(types.NewInt(leftInt), types.NewInt(rightInt))), true
 		case "-":
-			return leftInt - rightInt, true
+			return foldedNumber(types.Sub(types.NewInt(leftInt), types.NewInt(rightInt))), true
 		case "*":
-			return leftInt * rightInt, true
+			return foldedNumber(types.Mul(types.NewInt(leftInt), types.NewInt(rightInt))), true
 		case "/":
 			if rightInt == 0 {
 				return nil, false // Don't fold division by zero
 			}
-			return leftInt / rightInt, true
+			if leftInt%rightInt == 0 && !(leftInt == math.MinInt64 && rightInt == -1) {
+				return leftInt / rightInt, true
+			}
+			return float64(leftInt) / float64(rightInt), true
 		case "%":
 			if rightInt == 0 {
 				return nil, false // Don't fold modulo by zero
 			}
 			return leftInt % rightInt, true
 		case "**":
-			// Simple power for small exponents
+			// Only small exponents are folded
 			if rightInt >= 0 && rightInt < 100 {
-				result := int64(1)
-				for i := int64(0); i < rightInt; i++ {
-					result *= leftInt
-				}
-				return result, true
+				return foldedNumber(types.Pow(types.NewInt(leftInt), types.NewInt(rightInt))), true
 			}
 			return nil, false
 		case "==":
@@ -2219,8 +2931,14 @@ func foldConstantBinaryOp(left, right interface{}, operator string) (interface{}
 		case "^":
 			return leftInt ^ rightInt, true
 		case "<<":
+			if rightInt < 0 {
+				return nil, false // Don't fold the ArithmeticError
+			}
 			return leftInt << uint(rightInt), true
 		case ">>":
+			if rightInt < 0 {
+				return nil, false
+			}
 			return leftInt >> uint(rightInt), true
 		case "<=>":
 			if leftInt < rightInt {
@@ -2232,25 +2950,11 @@ func foldConstantBinaryOp(left, right interface{}, operator string) (interface{}
 		}
 	}
 
-	// Arithmetic operations on floats (or mixed int/float)
-	var leftFloat, rightFloat float64
-	var hasFloat bool
-	if leftIsInt {
-		leftFloat = float64(leftInt)
-		hasFloat = true
-	} else if f, ok := left.(float64); ok {
-		leftFloat = f
-		hasFloat = true
-	}
-	if rightIsInt {
-		rightFloat = float64(rightInt)
-		hasFloat = true
-	} else if f, ok := right.(float64); ok {
-		rightFloat = f
-		hasFloat = true
-	}
-
-	if hasFloat && (leftIsInt || rightIsInt || left != nil && right != nil) {
+	// Arithmetic operations on floats (or mixed int/float). Strings, bools
+	// and null convert by PHP's rules, which are left to the VM.
+	leftFloat, leftIsNumber := foldedFloat(left)
+	rightFloat, rightIsNumber := foldedFloat(right)
+	if leftIsNumber && rightIsNumber {
 		switch operator {
 		case "+":
 			return leftFloat + rightFloat, true
@@ -2304,6 +3008,17 @@ func foldConstantBinaryOp(left, right interface{}, operator string) (interface{}
 	return nil, false
 }
 
+// foldedFloat returns the value of an int or float constant as a float
+func foldedFloat(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case int64:
+		return float64(value), true
+	case float64:
+		return value, true
+	}
+	return 0, false
+}
+
 // foldConstantUnaryOp performs constant folding for unary operations
 // Returns (result value, success boolean)
 func foldConstantUnaryOp(operand interface{}, operator string) (interface{}, bool) {
@@ -2325,9 +3040,9 @@ func foldConstantUnaryOp(operand interface{}, operator string) (interface{}, boo
 		}
 
 	case "-":
-		// Unary minus
+		// Unary minus; -PHP_INT_MIN overflows to float
 		if i, ok := operand.(int64); ok {
-			return -i, true
+			return foldedNumber(types.Mul(types.NewInt(i), types.NewInt(-1))), true
 		}
 		if f, ok := operand.(float64); ok {
 			return -f, true
@@ -2343,157 +3058,57 @@ func foldConstantUnaryOp(operand interface{}, operator string) (interface{}, boo
 	return nil, false
 }
 
-// ============================================================================
-// Strength Reduction Optimization
-// ============================================================================
-
-// applyStrengthReduction applies strength reduction optimizations
-// Replaces expensive operations with cheaper equivalents
-// Returns (optimized bool, error)
-func (c *Compiler) applyStrengthReduction(node *ast.InfixExpression) (bool, error) {
-	// Check for multiplication by 0: x * 0 = 0
-	if node.Operator == "*" {
-		if isConstantZero(node.Right) {
-			// Result is always 0
-			constIdx := c.AddConstant(int64(0))
-			c.EmitWithLine(vm.OpQMAssign, uint32(node.Token.Pos.Line),
-				vm.ConstOperand(uint32(constIdx)),
-				vm.UnusedOperand(),
-				vm.TmpVarOperand(0))
-			return true, nil
-		}
-		if isConstantZero(node.Left) {
-			// 0 * x = 0
-			constIdx := c.AddConstant(int64(0))
-			c.EmitWithLine(vm.OpQMAssign, uint32(node.Token.Pos.Line),
-				vm.ConstOperand(uint32(constIdx)),
-				vm.UnusedOperand(),
-				vm.TmpVarOperand(0))
-			return true, nil
-		}
-	}
-
-	// Check for multiplication by 1: x * 1 = x
-	if node.Operator == "*" && isConstantOne(node.Right) {
-		// Just compile the left operand
-		return true, c.Compile(node.Left)
-	}
-	if node.Operator == "*" && isConstantOne(node.Left) {
-		// 1 * x = x
-		return true, c.Compile(node.Right)
-	}
-
-	// Check for addition/subtraction by 0: x + 0 = x, x - 0 = x
-	if (node.Operator == "+" || node.Operator == "-") && isConstantZero(node.Right) {
-		// x + 0 = x, x - 0 = x
-		return true, c.Compile(node.Left)
-	}
-	if node.Operator == "+" && isConstantZero(node.Left) {
-		// 0 + x = x
-		return true, c.Compile(node.Right)
-	}
-
-	// Check for multiplication by power of 2 (can use bit shift)
-	// x * 2 → x << 1, x * 4 → x << 2, etc.
-	if node.Operator == "*" {
-		if powerOf2, shiftAmount := isPowerOfTwo(node.Right); powerOf2 {
-			// Compile left operand
-			if err := c.Compile(node.Left); err != nil {
-				return true, err
-			}
-
-			// Create constant for shift amount
-			shiftIdx := c.AddConstant(int64(shiftAmount))
-
-			// Emit shift left instead of multiply
-			c.EmitWithLine(vm.OpSL, uint32(node.Token.Pos.Line),
-				vm.TmpVarOperand(0),          // Value to shift
-				vm.ConstOperand(uint32(shiftIdx)), // Shift amount
-				vm.TmpVarOperand(0))           // Result
-			return true, nil
-		}
-		if powerOf2, shiftAmount := isPowerOfTwo(node.Left); powerOf2 {
-			// 2 * x → x << 1
-			if err := c.Compile(node.Right); err != nil {
-				return true, err
-			}
-
-			shiftIdx := c.AddConstant(int64(shiftAmount))
-			c.EmitWithLine(vm.OpSL, uint32(node.Token.Pos.Line),
-				vm.TmpVarOperand(0),
-				vm.ConstOperand(uint32(shiftIdx)),
-				vm.TmpVarOperand(0))
-			return true, nil
-		}
+// foldedNumber returns the constant of a folded int or float
+func foldedNumber(v *types.Value) interface{} {
+	if v.IsInt() {
+		return v.ToInt()
 	}
+	return v.ToFloat()
+}
 
-	// Check for division by power of 2 (can use bit shift)
-	// x / 2 → x >> 1, x / 4 → x >> 2, etc.
-	if node.Operator == "/" {
-		if powerOf2, shiftAmount := isPowerOfTwo(node.Right); powerOf2 {
-			// Compile left operand
-			if err := c.Compile(node.Left); err != nil {
-				return true, err
-			}
-
-			// Create constant for shift amount
-			shiftIdx := c.AddConstant(int64(shiftAmount))
-
-			// Emit shift right instead of divide
-			c.EmitWithLine(vm.OpSR, uint32(node.Token.Pos.Line),
-				vm.TmpVarOperand(0),
-				vm.ConstOperand(uint32(shiftIdx)),
-				vm.TmpVarOperand(0))
-			return true, nil
-		}
+// compileInterpolatedString compiles "a $b c" into ROPE_INIT, ROPE_ADD and
+// ROPE_END, leaving the string in TmpVar(0). Literal parts are constant
+// operands and expressions are compiled into TmpVar(0) first.
+// ExtendedValue is the number of parts on ROPE_INIT and the part index on
+// ROPE_ADD and ROPE_END.
+func (c *Compiler) compileInterpolatedString(node *ast.InterpolatedStringExpression) error {
+	line := uint32(node.Token.Pos.Line)
+	if len(node.Parts) == 0 {
+		c.EmitWithLine(vm.OpQMAssign, line,
+			vm.ConstOperand(uint32(c.AddConstant(""))),
+			vm.UnusedOperand(),
+			vm.TmpVarOperand(0))
+		return nil
 	}
 
-	// No optimization applied
-	return false, nil
-}
+	rope := c.newTemp()
 
-// isConstantZero checks if an expression is the constant 0
-func isConstantZero(expr ast.Expr) bool {
-	if val, ok := getConstantValue(expr); ok {
-		switch v := val.(type) {
-		case int64:
-			return v == 0
-		case float64:
-			return v == 0.0
+	last := len(node.Parts) - 1
+	for i, part := range node.Parts {
+		operand := vm.TmpVarOperand(0)
+		if literal, ok := part.(*ast.StringLiteral); ok {
+			operand = vm.ConstOperand(uint32(c.AddConstant(literal.Value)))
+		} else if err := c.Compile(part); err != nil {
+			return err
 		}
-	}
-	return false
-}
 
-// isConstantOne checks if an expression is the constant 1
-func isConstantOne(expr ast.Expr) bool {
-	if val, ok := getConstantValue(expr); ok {
-		switch v := val.(type) {
-		case int64:
-			return v == 1
-		case float64:
-			return v == 1.0
+		switch {
+		case i == 0:
+			c.EmitWithExtended(vm.OpRopeInit, line, uint32(len(node.Parts)),
+				vm.UnusedOperand(), operand, rope)
+		case i == last:
+			c.EmitWithExtended(vm.OpRopeEnd, line, uint32(i),
+				rope, operand, vm.TmpVarOperand(0))
+		default:
+			c.EmitWithExtended(vm.OpRopeAdd, line, uint32(i),
+				rope, operand, rope)
 		}
 	}
-	return false
-}
 
-// isPowerOfTwo checks if an expression is a constant power of 2
-// Returns (isPowerOf2 bool, shiftAmount int)
-func isPowerOfTwo(expr ast.Expr) (bool, int) {
-	if val, ok := getConstantValue(expr); ok {
-		if i, ok := val.(int64); ok && i > 0 {
-			// Check if it's a power of 2 using bit manipulation
-			// A number is a power of 2 if it has only one bit set
-			if (i & (i - 1)) == 0 {
-				// Calculate shift amount (log2)
-				shiftAmount := 0
-				for n := i; n > 1; n >>= 1 {
-					shiftAmount++
-				}
-				return true, shiftAmount
-			}
-		}
+	// A lone expression ("$x") is still converted to a string
+	if last == 0 {
+		c.EmitWithExtended(vm.OpRopeEnd, line, 1,
+			rope, vm.UnusedOperand(), vm.TmpVarOperand(0))
 	}
-	return false, 0
+	return nil
 }
@@ -1,10 +1,13 @@
 package compiler
 
 import (
+	"fmt"
 	"testing"
+	"unsafe"
 
 	"github.com/krizos/php-go/pkg/lexer"
 	"github.com/krizos/php-go/pkg/parser"
+	"github.com/krizos/php-go/pkg/types"
 	"github.com/krizos/php-go/pkg/vm"
 )
 
@@ -30,6 +33,40 @@ func parseAndCompile(t *testing.T, input string) *Bytecode {
 	return c.Bytecode()
 }
 
+// allInstructions returns the instructions of the main op array followed by
+// those of each op array of the function table
+func allInstructions(bytecode *Bytecode) vm.Instructions {
+	instructions := append(vm.Instructions{}, bytecode.Instructions...)
+	for _, fn := range bytecode.Functions {
+		instructions = append(instructions, fn.Instructions...)
+	}
+	return instructions
+}
+
+// allConstants returns the constants of every op array
+func allConstants(bytecode *Bytecode) []interface{} {
+	constants := append([]interface{}{}, bytecode.Constants...)
+	for _, fn := range bytecode.Functions {
+		constants = append(constants, fn.Constants...)
+	}
+	return constants
+}
+
+// compileSource parses and compiles input, returning the compile error
+func compileSource(input string) (*Bytecode, error) {
+	p := parser.New(lexer.New(input, "test.php"))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, fmt.Errorf("parser errors: %v", p.Errors())
+	}
+
+	c := New()
+	if err := c.Compile(program); err != nil {
+		return nil, err
+	}
+	return c.Bytecode(), nil
+}
+
 // ========================================
 // Constant Table Tests
 // ========================================
@@ -61,6 +98,19 @@ func TestAddConstant(t *testing.T) {
 	}
 }
 
+func TestAddConstantInternsStrings(t *testing.T) {
+	first, second := New(), New()
+	a := first.constants[first.AddConstant(string([]byte("shared")))].(string)
+	b := second.constants[second.AddConstant(string([]byte("shared")))].(string)
+
+	if unsafe.StringData(a) != unsafe.StringData(b) {
+		t.Error("Equal string constants of two compilers should share their bytes")
+	}
+	if unsafe.StringData(a) != unsafe.StringData(types.InternString("shared")) {
+		t.Error("String constants should be the interned copies")
+	}
+}
+
 func TestGetConstant(t *testing.T) {
 	c := New()
 
@@ -384,7 +434,7 @@ func TestCompilePrefixExpressions(t *testing.T) {
 	}{
 		// Use variables to prevent constant folding
 		{"<?php !$x;", vm.OpBoolNot},
-		{"<?php -$x;", vm.OpSub}, // Unary minus becomes 0 - x
+		{"<?php -$x;", vm.OpMul}, // Unary minus becomes x * -1
 		{"<?php ~$x;", vm.OpBWNot},
 	}
 
@@ -406,6 +456,47 @@ func TestCompilePrefixExpressions(t *testing.T) {
 	}
 }
 
+func TestCompileSilence(t *testing.T) {
+	bytecode := parseAndCompile(t, "<?php @$x;")
+
+	var opcodes []vm.Opcode
+	for _, instr := range bytecode.Instructions {
+		opcodes = append(opcodes, instr.Opcode)
+	}
+	if len(opcodes) < 3 || opcodes[0] != vm.OpBeginSilence || opcodes[1] != vm.OpFetchR || opcodes[2] != vm.OpEndSilence {
+		t.Fatalf("Expected the operand between BEGIN_SILENCE and END_SILENCE, got %v", opcodes)
+	}
+}
+
+func TestCompileExit(t *testing.T) {
+	tests := []struct {
+		input     string
+		hasStatus bool
+	}{
+		{"<?php exit;", false},
+		{"<?php exit(2);", true},
+		{"<?php die('bye');", true},
+	}
+
+	for _, tt := range tests {
+		bytecode := parseAndCompile(t, tt.input)
+
+		var exit *vm.Instruction
+		for i := range bytecode.Instructions {
+			if bytecode.Instructions[i].Opcode == vm.OpExit {
+				exit = &bytecode.Instructions[i]
+				break
+			}
+		}
+		if exit == nil {
+			t.Fatalf("%s: expected EXIT instruction", tt.input)
+		}
+		if (exit.Op1.Type != vm.OpUnused) != tt.hasStatus {
+			t.Errorf("%s: unexpected status operand %v", tt.input, exit.Op1)
+		}
+	}
+}
+
 // ========================================
 // Compilation Tests - Statements
 // ========================================
@@ -842,17 +933,13 @@ func TestCompileShortTernary(t *testing.T) {
 
 	bytecode := parseAndCompile(t, input)
 
-	// Should have JMP_SET opcode
-	hasJmpSet := false
-	for _, instr := range bytecode.Instructions {
-		if instr.Opcode == vm.OpJmpSet {
-			hasJmpSet = true
-			break
-		}
+	// A truthy condition jumps past the alternative, staying in temp 0
+	instr, ok := findOpcode(bytecode.Instructions, vm.OpJmpNZ)
+	if !ok {
+		t.Fatal("Expected JMPNZ instruction for short ternary")
 	}
-
-	if !hasJmpSet {
-		t.Error("Expected JMP_SET instruction for short ternary")
+	if instr.Op1 != vm.TmpVarOperand(0) || !instr.Op2.IsConst() {
+		t.Errorf("Expected JMPNZ T0, <end>, got %v", instr)
 	}
 }
 
@@ -918,6 +1005,34 @@ func TestCompileInstanceof(t *testing.T) {
 	}
 }
 
+func TestCompileInstanceofOperands(t *testing.T) {
+	input := `<?php
+	$a = $obj instanceof Foo;
+	$b = $obj instanceof $class;
+	`
+
+	bytecode := parseAndCompile(t, input)
+
+	var instanceofs []vm.Instruction
+	for _, instr := range bytecode.Instructions {
+		if instr.Opcode == vm.OpInstanceof {
+			instanceofs = append(instanceofs, instr)
+		}
+	}
+	if len(instanceofs) != 2 {
+		t.Fatalf("Expected 2 INSTANCEOF instructions, got %d", len(instanceofs))
+	}
+
+	// A class name is a constant operand
+	if instanceofs[0].Op2.Type != vm.OpConst || bytecode.Constants[instanceofs[0].Op2.Value] != "Foo" {
+		t.Errorf("Expected constant class name Foo, got %v", instanceofs[0].Op2)
+	}
+	// A class expression must not overwrite the object
+	if instanceofs[1].Op1 == instanceofs[1].Op2 {
+		t.Errorf("Object and class share operand %v", instanceofs[1].Op1)
+	}
+}
+
 func TestCompileGroupedExpression(t *testing.T) {
 	// Use variables to prevent constant folding
 	input := `<?php
@@ -1237,6 +1352,675 @@ func TestCompileForeachWithKey(t *testing.T) {
 	}
 }
 
+func TestCompileListAssignment(t *testing.T) {
+	bytecode := parseAndCompile(t, "<?php [$a, ['j' => $b, 'k' => $c]] = [1, ['j' => 2, 'k' => 3]];")
+
+	var fetches []vm.Instruction
+	assigns := 0
+	for _, instr := range bytecode.Instructions {
+		switch instr.Opcode {
+		case vm.OpFetchListR:
+			fetches = append(fetches, instr)
+		case vm.OpAssign:
+			assigns++
+		}
+	}
+	if len(fetches) != 4 || assigns != 3 {
+		t.Fatalf("Expected 4 FETCH_LIST_R and 3 ASSIGN, got %d and %d", len(fetches), assigns)
+	}
+
+	// The nested list reads the element fetched for it
+	if fetches[2].Op1 != fetches[1].Result {
+		t.Errorf("Nested list should read %v, got %v", fetches[1].Result, fetches[2].Op1)
+	}
+	keys := []interface{}{int64(0), int64(1), "j", "k"}
+	for i, fetch := range fetches {
+		if key := bytecode.Constants[fetch.Op2.Value]; key != keys[i] {
+			t.Errorf("fetch %d: expected key %v, got %v", i, keys[i], key)
+		}
+	}
+}
+
+func TestCompileListByReference(t *testing.T) {
+	bytecode := parseAndCompile(t, "<?php [$a, &$b] = $arr;")
+
+	var opcodes []vm.Opcode
+	for _, instr := range bytecode.Instructions {
+		opcodes = append(opcodes, instr.Opcode)
+	}
+	expected := []vm.Opcode{vm.OpFetchListR, vm.OpAssign, vm.OpFetchListW, vm.OpAssignRef}
+	for i, op := range expected {
+		if i >= len(opcodes) || opcodes[i] != op {
+			t.Fatalf("Expected %v, got %v", expected, opcodes)
+		}
+	}
+
+	// References need a variable to bind into
+	if _, err := compileSource("<?php [&$a] = [1];"); err == nil {
+		t.Error("Expected an error for a reference into a temporary")
+	}
+}
+
+func TestCompileListErrors(t *testing.T) {
+	for _, input := range []string{
+		"<?php ['k' => $a, $b] = $x;",
+		"<?php $x = [1, , 2];",
+	} {
+		if _, err := compileSource(input); err == nil {
+			t.Errorf("%s: expected a compile error", input)
+		}
+	}
+}
+
+func TestCompileForeachDestructuring(t *testing.T) {
+	bytecode := parseAndCompile(t, "<?php foreach ($rows as [$id, $name]) { echo $id; }")
+
+	fetches := 0
+	for _, instr := range bytecode.Instructions {
+		if instr.Opcode == vm.OpFetchListR {
+			fetches++
+		}
+	}
+	if fetches != 2 {
+		t.Errorf("Expected 2 FETCH_LIST_R instructions, got %d", fetches)
+	}
+}
+
+func TestCompileCallArguments(t *testing.T) {
+	bytecode := parseAndCompile(t, "<?php f(1, ...$rest, b: 2);")
+
+	var sends []vm.Instruction
+	initPos, callPos := -1, -1
+	for i, instr := range bytecode.Instructions {
+		switch instr.Opcode {
+		case vm.OpInitFcallByName:
+			initPos = i
+		case vm.OpSendVal, vm.OpSendUnpack:
+			if initPos < 0 {
+				t.Fatalf("Argument sent before the call was initialized")
+			}
+			sends = append(sends, instr)
+		case vm.OpDoFcall:
+			callPos = i
+		}
+	}
+	if len(sends) != 3 || callPos < initPos {
+		t.Fatalf("Expected 3 sends between INIT and DO_FCALL, got %d", len(sends))
+	}
+	if sends[0].Opcode != vm.OpSendVal || !sends[0].Op2.IsUnused() {
+		t.Errorf("Expected a positional SEND_VAL, got %v", sends[0])
+	}
+	if sends[1].Opcode != vm.OpSendUnpack {
+		t.Errorf("Expected SEND_UNPACK, got %v", sends[1].Opcode)
+	}
+	if name := bytecode.Constants[sends[2].Op2.Value]; sends[2].Opcode != vm.OpSendVal || name != "b" {
+		t.Errorf("Expected SEND_VAL named b, got %v (%v)", sends[2], name)
+	}
+}
+
+func TestCompileNamedArgumentErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"<?php f(a: 1, 2);", "cannot use positional argument after named argument"},
+		{"<?php f(...$x, 2);", "cannot use positional argument after argument unpacking"},
+		{"<?php f(a: 1, ...$x);", "cannot use argument unpacking after named arguments"},
+		{"<?php f(a: 1, a: 2);", "duplicate named parameter $a"},
+		// Calls to functions declared in the file are checked against
+		// their signature, wherever the declaration is
+		{"<?php g(c: 1); function g($a, $b = 2) {}", "unknown named parameter $c"},
+		{"<?php function g($a, $b = 2) {} g(1, a: 1);", "named parameter $a overwrites previous argument"},
+		{"<?php namespace N; g(c: 1); function g($a) {}", "unknown named parameter $c"},
+	}
+
+	for _, tt := range tests {
+		_, err := compileSource(tt.input)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.err, err)
+		}
+	}
+
+	// Named arguments matching the signature, or collected by a variadic
+	for _, input := range []string{
+		"<?php function g($a, $b = 2) {} g(b: 1, a: 2);",
+		"<?php function g(...$rest) {} g(x: 1);",
+		"<?php strlen(string: 'x');",
+	} {
+		if _, err := compileSource(input); err != nil {
+			t.Errorf("%s: unexpected error %v", input, err)
+		}
+	}
+}
+
+func TestCompileCoalesce(t *testing.T) {
+	bytecode := parseAndCompile(t, "<?php $x = $a['k']->p ?? 1;")
+
+	// The left side is fetched without notices, the array from the
+	// compiled variable itself
+	for _, opcode := range []vm.Opcode{vm.OpFetchDimIs, vm.OpFetchObjIs} {
+		if _, ok := findOpcode(bytecode.Instructions, opcode); !ok {
+			t.Errorf("Expected %s for the left operand", opcode)
+		}
+	}
+	for _, opcode := range []vm.Opcode{vm.OpFetchR, vm.OpFetchIs, vm.OpFetchDimR} {
+		if _, ok := findOpcode(bytecode.Instructions, opcode); ok {
+			t.Errorf("Expected no %s in the left operand", opcode)
+		}
+	}
+
+	// COALESCE jumps over the right operand to the assignment
+	for i, instr := range bytecode.Instructions {
+		if instr.Opcode != vm.OpCoalesce {
+			continue
+		}
+		target := int(instr.Op2.Value)
+		if target <= i || bytecode.Instructions[target].Opcode != vm.OpAssign {
+			t.Errorf("Expected COALESCE to jump to ASSIGN, jumps to %d", target)
+		}
+		return
+	}
+	t.Fatal("Expected COALESCE instruction")
+}
+
+func TestCompileCoalesceAssignment(t *testing.T) {
+	tests := []struct {
+		input  string
+		fetch  vm.Opcode
+		assign vm.Opcode
+	}{
+		{"<?php $x ??= f();", vm.OpFetchIs, vm.OpAssign},
+		{"<?php $a['k'] ??= f();", vm.OpFetchDimIs, vm.OpAssignDim},
+		{"<?php $o->p ??= f();", vm.OpFetchObjIs, vm.OpAssignObj},
+	}
+
+	for _, tt := range tests {
+		bytecode := parseAndCompile(t, tt.input)
+		ops := make([]vm.Opcode, 0, len(bytecode.Instructions))
+		coalesce := -1
+		for i, instr := range bytecode.Instructions {
+			ops = append(ops, instr.Opcode)
+			if instr.Opcode == vm.OpCoalesce {
+				coalesce = i
+			}
+		}
+		if coalesce < 1 || ops[coalesce-1] != tt.fetch {
+			t.Fatalf("%s: expected %s before COALESCE, got %v", tt.input, tt.fetch, ops)
+		}
+
+		// The call and the assignment are both skipped
+		target := int(bytecode.Instructions[coalesce].Op2.Value)
+		if ops[target-1] != tt.assign {
+			t.Errorf("%s: expected COALESCE to skip %s, got %v", tt.input, tt.assign, ops)
+		}
+		if _, ok := findOpcode(bytecode.Instructions[:coalesce], vm.OpInitFcallByName); ok {
+			t.Errorf("%s: right side evaluated before COALESCE", tt.input)
+		}
+	}
+}
+
+func TestCompileNullsafeChain(t *testing.T) {
+	bytecode := parseAndCompile(t, "<?php echo $a?->b->c()?->d;")
+
+	var jumps []int
+	for i, instr := range bytecode.Instructions {
+		if instr.Opcode == vm.OpJmpNull {
+			jumps = append(jumps, i)
+		}
+	}
+	if len(jumps) != 2 {
+		t.Fatalf("Expected 2 JMP_NULL instructions, got %d", len(jumps))
+	}
+
+	// Both short-circuit the whole chain, to the instruction after its
+	// last fetch
+	echo, _ := findOpcode(bytecode.Instructions, vm.OpEcho)
+	for _, pos := range jumps {
+		target := int(bytecode.Instructions[pos].Op2.Value)
+		if bytecode.Instructions[target-1].Opcode != vm.OpFetchObjR || bytecode.Instructions[target].Opcode != echo.Opcode {
+			t.Errorf("JMP_NULL at %d should jump to the end of the chain, jumps to %d", pos, target)
+		}
+	}
+}
+
+func TestCompileNullsafeErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"<?php $a?->b = 1;", "cannot use nullsafe operator in write context"},
+		{"<?php $a?->b->c = 1;", "cannot use nullsafe operator in write context"},
+		{"<?php $a?->b ??= 1;", "cannot use nullsafe operator in write context"},
+		{"<?php $f = $a?->b(...);", "cannot combine nullsafe operator with Closure creation"},
+	}
+
+	for _, tt := range tests {
+		_, err := compileSource(tt.input)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.err, err)
+		}
+	}
+}
+
+func TestCompileIssetEmptyUnset(t *testing.T) {
+	tests := []struct {
+		input   string
+		opcodes []vm.Opcode
+	}{
+		{"<?php isset($a);", []vm.Opcode{vm.OpIssetIsemptyCV}},
+		{"<?php isset($a['x']['y']);", []vm.Opcode{vm.OpFetchDimIs, vm.OpIssetIsemptyDimObj}},
+		{"<?php isset($o->p);", []vm.Opcode{vm.OpFetchIs, vm.OpIssetIsemptyPropObj}},
+		{"<?php isset(A::$p);", []vm.Opcode{vm.OpIssetIsemptyStaticProp}},
+		{"<?php isset($a, $b);", []vm.Opcode{vm.OpIssetIsemptyCV, vm.OpJmpZ, vm.OpIssetIsemptyCV}},
+		{"<?php empty(f());", []vm.Opcode{vm.OpInitFcallByName, vm.OpDoFcall, vm.OpBoolNot}},
+		{"<?php unset($a);", []vm.Opcode{vm.OpUnsetCV}},
+		{"<?php unset($a['x']['y']);", []vm.Opcode{vm.OpFetchDimUnset, vm.OpUnsetDim}},
+		{"<?php unset($o->p->q);", []vm.Opcode{vm.OpFetchObjUnset, vm.OpUnsetObj}},
+		{"<?php unset(A::$p);", []vm.Opcode{vm.OpUnsetStaticProp}},
+	}
+
+	for _, tt := range tests {
+		bytecode := parseAndCompile(t, tt.input)
+		var opcodes []vm.Opcode
+		for _, instr := range bytecode.Instructions {
+			if instr.Opcode != vm.OpFree && instr.Opcode != vm.OpReturn {
+				opcodes = append(opcodes, instr.Opcode)
+			}
+		}
+		if fmt.Sprint(opcodes) != fmt.Sprint(tt.opcodes) {
+			t.Errorf("%s: expected %v, got %v", tt.input, tt.opcodes, opcodes)
+		}
+	}
+
+	// empty() sets the flag of the check, and of the short-circuit of a
+	// nullsafe chain
+	bytecode := parseAndCompile(t, "<?php empty($a?->b);")
+	check, _ := findOpcode(bytecode.Instructions, vm.OpIssetIsemptyPropObj)
+	jump, _ := findOpcode(bytecode.Instructions, vm.OpJmpNull)
+	if check.ExtendedValue != vm.IssetIsEmpty || jump.ExtendedValue != vm.JmpNullEmpty {
+		t.Errorf("Expected empty() flags, got %d and %d", check.ExtendedValue, jump.ExtendedValue)
+	}
+
+	for input, want := range map[string]string{
+		"<?php isset(f());":    "cannot use isset() on the result of an expression (you can use \"null !== expression\" instead)",
+		"<?php unset($a?->b);": "cannot use nullsafe operator in write context",
+	} {
+		if _, err := compileSource(input); err == nil || err.Error() != want {
+			t.Errorf("%s: expected error %q, got %v", input, want, err)
+		}
+	}
+}
+
+func TestCompileReadonlyPropertyErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"<?php class A { public readonly $x; }", "readonly property A::$x must have type"},
+		{"<?php class A { public readonly int $x = 1; }", "readonly property A::$x cannot have default value"},
+		{"<?php class A { public static readonly int $x; }", "static property A::$x cannot be readonly"},
+		{"<?php readonly class A { public $x; }", "readonly property A::$x must have type"},
+	}
+
+	for _, tt := range tests {
+		_, err := compileSource(tt.input)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.err, err)
+		}
+	}
+
+	if _, err := compileSource("<?php readonly class A { public int $x; protected readonly string $y; }"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestCompileLateStaticBinding(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php namespace App; static::create(); parent::build(); Model::find(); new static;`)
+
+	want := [][2]string{{"static", "create"}, {"parent", "build"}, {"App\\Model", "find"}}
+	var calls [][2]string
+	for _, instr := range bytecode.Instructions {
+		switch instr.Opcode {
+		case vm.OpInitStaticMethodCall:
+			if instr.Op1.Type != vm.OpConst || instr.Op2.Type != vm.OpConst {
+				t.Fatalf("Expected constant class and method operands, got %v and %v", instr.Op1, instr.Op2)
+			}
+			calls = append(calls, [2]string{bytecode.Constants[instr.Op1.Value].(string), bytecode.Constants[instr.Op2.Value].(string)})
+		}
+	}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("Expected static calls %v, got %v", want, calls)
+	}
+
+	if _, ok := findOpcode(bytecode.Instructions, vm.OpNew); !ok {
+		t.Error("Expected NEW for new static")
+	}
+}
+
+func TestCompileEnum(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+enum Suit: string {
+	case Hearts = 'H';
+	case Spades = 'S';
+	public function label(): string { return ucfirst($this->name); }
+}
+echo Suit::Hearts->value;`)
+
+	fetch, ok := findOpcode(bytecode.Instructions, vm.OpFetchClassConstant)
+	if !ok {
+		t.Fatal("Expected FETCH_CLASS_CONSTANT for Suit::Hearts")
+	}
+	if bytecode.Constants[fetch.Op1.Value] != "Suit" || bytecode.Constants[fetch.Op2.Value] != "Hearts" {
+		t.Errorf("Expected Suit::Hearts operands, got %v and %v", bytecode.Constants[fetch.Op1.Value], bytecode.Constants[fetch.Op2.Value])
+	}
+
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"<?php enum E: float { case A = 1.5; }", "enum backing type must be int or string, float given"},
+		{"<?php enum E: int { case A; }", "case A of backed enum E must have a value"},
+		{"<?php enum E { case A = 1; }", "case A of non-backed enum E must not have a value"},
+		{"<?php enum E: int { case A = 'a'; }", "enum case type string does not match enum backing type int"},
+		{"<?php enum E: int { case A = 1; case B = 2 - 1; }", "duplicate value in enum E for cases A and B"},
+		{"<?php enum E: int { case A = $x; }", "enum case value must be compile-time evaluatable"},
+		{"<?php enum E { case A; case A; }", "cannot redefine class constant E::A"},
+		{"<?php enum E { case A; public $x; }", "enum E cannot include properties"},
+		{"<?php enum E { case A; public function __construct() {} }", "enum E cannot include magic method __construct"},
+	}
+
+	for _, tt := range tests {
+		_, err := compileSource(tt.input)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.err, err)
+		}
+	}
+}
+
+func TestCompileTraits(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+trait Hello {
+	const GREETING = 'Hello';
+	public static $count = 0;
+	public function hi() { return self::GREETING; }
+	abstract public function name();
+	public static function make() { return new static; }
+}
+trait World {
+	public function hi() { return 'World'; }
+}
+class Greeter {
+	use Hello, World {
+		Hello::hi insteadof World;
+		World::hi as protected worldHi;
+		make as private;
+	}
+	public function name() { return 'Greeter'; }
+}`)
+
+	hello := findClassDecl(t, bytecode, "Hello")
+	if !hello.Class.IsTrait {
+		t.Error("Expected Hello to be declared as a trait")
+	}
+	if _, ok := hello.Class.Constants["GREETING"]; !ok {
+		t.Error("Expected trait constant GREETING")
+	}
+	if prop := hello.Class.Properties["count"]; prop == nil || !prop.IsStatic || prop.Default.ToInt() != 0 {
+		t.Error("Expected static trait property $count with default 0")
+	}
+	if method := hello.Class.Methods["name"]; method == nil || !method.IsAbstract {
+		t.Error("Expected abstract trait method name()")
+	}
+	if method := hello.Class.Methods["make"]; method == nil || !method.IsStatic {
+		t.Error("Expected static trait method make()")
+	}
+	for _, name := range []string{"hi", "make"} {
+		if method := hello.Class.Methods[name]; method == nil || len(method.Instructions) == 0 {
+			t.Errorf("Expected a compiled body for %s()", name)
+		}
+	}
+	if method := hello.Class.Methods["name"]; method == nil || method.Instructions != nil {
+		t.Error("Expected no body for the abstract method name()")
+	}
+
+	greeter := findClassDecl(t, bytecode, "Greeter")
+	if fmt.Sprint(greeter.Traits) != "[Hello World]" {
+		t.Errorf("Expected traits [Hello World], got %v", greeter.Traits)
+	}
+	if greeter.Class.TraitPrecedence["hi"] != "Hello" {
+		t.Errorf("Expected hi() from Hello, got %q", greeter.Class.TraitPrecedence["hi"])
+	}
+	aliases := map[string]string{"worldHi": "World::hi:protected", "make": "::make:private"}
+	for alias, spec := range aliases {
+		if greeter.Class.TraitAliases[alias] != spec {
+			t.Errorf("Expected alias %s => %q, got %q", alias, spec, greeter.Class.TraitAliases[alias])
+		}
+	}
+
+	declared := 0
+	for _, instr := range bytecode.Instructions {
+		if instr.Opcode == vm.OpDeclareClass {
+			declared++
+		}
+	}
+	if declared != 3 {
+		t.Errorf("Expected 3 DECLARE_CLASS instructions, got %d", declared)
+	}
+
+	_, err := compileSource("<?php class C { use A, B { A::m insteadof A; } }")
+	if err == nil || err.Error() != "inconsistent insteadof definition: the method m is to be used from A, but A is also on the exclude list" {
+		t.Errorf("Expected an inconsistent insteadof error, got %v", err)
+	}
+}
+
+func TestCompileAttributes(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+namespace App;
+use Framework\Route;
+
+#[\Attribute(\Attribute::TARGET_CLASS | \Attribute::TARGET_METHOD)]
+class Tag {}
+
+#[Tag, Route("/users", methods: ["GET"])]
+class Users {
+	#[Tag] const LIMIT = 10;
+	#[Tag] public $items = [];
+
+	#[Route(path: Users::class)]
+	public function index(#[\SensitiveParameter] $token) {}
+}
+
+#[Tag(1 + 2)]
+function helper() {}`)
+
+	tag := findClassDecl(t, bytecode, "App\\Tag")
+	if len(tag.Class.Attributes) != 1 || tag.Class.Attributes[0].Name != "Attribute" {
+		t.Fatalf("Expected #[Attribute] on Tag, got %v", tag.Class.Attributes)
+	}
+	if expr := tag.Class.Attributes[0].Arguments[0].Expr; expr == nil || expr.Operator != "|" || expr.Left.Class != "Attribute" || expr.Left.Constant != "TARGET_CLASS" {
+		t.Errorf("Expected the flags to be kept as a constant expression, got %+v", expr)
+	}
+
+	users := findClassDecl(t, bytecode, "App\\Users")
+	attrs := users.Class.Attributes
+	if len(attrs) != 2 || attrs[0].Name != "App\\Tag" || attrs[1].Name != "Framework\\Route" {
+		t.Fatalf("Expected attributes App\\Tag and Framework\\Route, got %v", attrs)
+	}
+	route := attrs[1].Arguments
+	if len(route) != 2 || route[0].Name != "" || route[0].Value.ToString() != "/users" || route[1].Name != "methods" || !route[1].Value.IsArray() {
+		t.Errorf("Unexpected Route arguments %+v", route)
+	}
+	if len(users.Class.Constants["LIMIT"].Attributes) != 1 || len(users.Class.Properties["items"].Attributes) != 1 {
+		t.Error("Expected attributes on the constant and the property")
+	}
+
+	index := users.Class.Methods["index"]
+	if len(index.Attributes) != 1 || index.Attributes[0].Arguments[0].Value.ToString() != "App\\Users" {
+		t.Errorf("Expected #[Route(path: Users::class)] on index(), got %v", index.Attributes)
+	}
+	if params := index.Parameters; len(params[0].Attributes) != 1 || params[0].Attributes[0].Name != "SensitiveParameter" {
+		t.Errorf("Expected #[SensitiveParameter] on $token")
+	}
+
+	var helper *vm.FunctionDecl
+	for _, c := range bytecode.Constants {
+		if decl, ok := c.(*vm.FunctionDecl); ok {
+			helper = decl
+		}
+	}
+	if helper == nil || helper.Function.Name != "App\\helper" || len(helper.Function.Attributes) != 1 ||
+		helper.Function.Attributes[0].Arguments[0].Value.ToInt() != 3 {
+		t.Errorf("Expected #[Tag(3)] on helper(), got %+v", helper)
+	}
+
+	errors := map[string]string{
+		"<?php #[self] class C {}":          "cannot use 'self' as attribute name as it is reserved",
+		"<?php #[A(...$args)] class C {}":   "cannot use unpacking in attribute argument list",
+		"<?php #[A($x)] function f() {}":    "constant expression contains invalid operations",
+		"<?php #[A(name: 1, 2)] class C {}": "cannot use positional argument after named argument",
+	}
+	for input, want := range errors {
+		_, err := compileSource(input)
+		if err == nil || err.Error() != want {
+			t.Errorf("%s: expected error %q, got %v", input, want, err)
+		}
+	}
+}
+
+func TestCompileDocComments(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+/** A user */
+class User {
+	/** Maximum length */
+	const MAX = 10;
+
+	/** @var string */
+	public $name;
+
+	/** Says hello */
+	public function greet() {}
+}
+
+/** @return int */
+function answer() { return 42; }`)
+
+	user := findClassDecl(t, bytecode, "User")
+	if user.Class.DocComment != "/** A user */" {
+		t.Errorf("Expected the class doc comment, got %q", user.Class.DocComment)
+	}
+	if got := user.Class.Constants["MAX"].DocComment; got != "/** Maximum length */" {
+		t.Errorf("Expected the constant doc comment, got %q", got)
+	}
+	if got := user.Class.Properties["name"].DocComment; got != "/** @var string */" {
+		t.Errorf("Expected the property doc comment, got %q", got)
+	}
+	if got := user.Class.Methods["greet"].DocComment; got != "/** Says hello */" {
+		t.Errorf("Expected the method doc comment, got %q", got)
+	}
+
+	for _, c := range bytecode.Constants {
+		if decl, ok := c.(*vm.FunctionDecl); ok && decl.Function.DocComment != "/** @return int */" {
+			t.Errorf("Expected the function doc comment, got %q", decl.Function.DocComment)
+		}
+	}
+}
+
+func TestCompileVerifyReturnType(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+function typed(): int { return 1; }
+function untyped() { return 1; }
+function nothing(): void { }
+function maybe(): ?int { if (true) { return null; } }`)
+
+	var verified []int
+	for _, instr := range allInstructions(bytecode) {
+		if instr.Opcode == vm.OpVerifyReturnType {
+			verified = append(verified, int(instr.Op1.Type))
+		}
+	}
+	// typed() checks its returned value, maybe() falling off its end
+	if len(verified) != 3 || verified[0] != int(vm.OpTmpVar) || verified[2] != int(vm.OpUnused) {
+		t.Errorf("Expected VERIFY_RETURN_TYPE for typed() and twice for maybe(), got operand types %v", verified)
+	}
+
+	errors := map[string]string{
+		`<?php function f(): void { return 1; }`:             "a void function must not return a value",
+		`<?php function f(): int { return; }`:                "a function with return type must return a value",
+		`<?php function f(): never { return; }`:              "a never-returning function must not return",
+		`<?php class C { function m(): void { return 1; } }`: "a void function must not return a value",
+	}
+	for input, expected := range errors {
+		if _, err := compileSource(input); err == nil || err.Error() != expected {
+			t.Errorf("%s: expected error %q, got %v", input, expected, err)
+		}
+	}
+
+	// Returns in closures are not checked against the enclosing function
+	if _, err := compileSource(`<?php function f(): void { $g = function () { return 1; }; }`); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCompileDeclareStrictTypes(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+declare(strict_types=1);
+function f(int $x) { return $x; }
+class C { function m() {} }`)
+
+	if !bytecode.StrictTypes {
+		t.Error("Expected strict_types to be recorded in the bytecode")
+	}
+	for _, c := range bytecode.Constants {
+		if decl, ok := c.(*vm.FunctionDecl); ok && !decl.Function.StrictTypes {
+			t.Error("Expected f() to be compiled in strict mode")
+		}
+	}
+	if method := findClassDecl(t, bytecode, "C").Class.Methods["m"]; !method.StrictTypes {
+		t.Error("Expected C::m() to be compiled in strict mode")
+	}
+	if parseAndCompile(t, `<?php function f(int $x) {}`).StrictTypes {
+		t.Error("Expected coercive mode without declare(strict_types=1)")
+	}
+	if !parseAndCompile(t, `<?php declare(ticks=1); declare(strict_types=1);`).StrictTypes {
+		t.Error("Expected declare statements to be allowed before strict_types")
+	}
+
+	errors := map[string]string{
+		`<?php echo 1; declare(strict_types=1);`:          "strict_types declaration must be the very first statement in the script",
+		`<?php function f() { declare(strict_types=1); }`: "strict_types declaration must be the very first statement in the script",
+		`<?php declare(strict_types=1) { echo 1; }`:       "strict_types declaration must not use block mode",
+		`<?php declare(strict_types=2);`:                  "strict_types declaration must have 0 or 1 as its value",
+	}
+	for input, expected := range errors {
+		if _, err := compileSource(input); err == nil || err.Error() != expected {
+			t.Errorf("%s: expected error %q, got %v", input, expected, err)
+		}
+	}
+}
+
+func TestCompileDeclareTicks(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+$a = 1;
+declare(ticks=3) {
+	$b = 2;
+	$c = 3;
+}
+$d = 4;
+declare(ticks=1);
+$e = 5;`)
+
+	var ticks []uint32
+	for _, instr := range bytecode.Instructions {
+		if instr.Opcode == vm.OpTicks {
+			ticks = append(ticks, instr.ExtendedValue)
+		}
+	}
+	// Two statements in the block, then the statement after declare(ticks=1);
+	if len(ticks) != 3 || ticks[0] != 3 || ticks[1] != 3 || ticks[2] != 1 {
+		t.Errorf("Expected TICKS 3, 3 and 1, got %v", ticks)
+	}
+}
+
 func TestCompileBreakStatement(t *testing.T) {
 	input := `<?php
 	while (true) {
@@ -1271,53 +2055,207 @@ func TestCompileContinueStatement(t *testing.T) {
 
 	bytecode := parseAndCompile(t, input)
 
-	// Should have multiple JMP opcodes
-	jmpCount := 0
+	// Should have multiple JMP opcodes
+	jmpCount := 0
+	for _, instr := range bytecode.Instructions {
+		if instr.Opcode == vm.OpJmp {
+			jmpCount++
+		}
+	}
+
+	if jmpCount < 3 {
+		t.Errorf("Expected at least 3 JMP instructions (if-end, continue, loop), got %d", jmpCount)
+	}
+}
+
+func TestCompileSwitchStatement(t *testing.T) {
+	input := `<?php
+	switch ($x) {
+		case 1:
+			echo "one";
+			break;
+		case 2:
+			echo "two";
+			break;
+		default:
+			echo "other";
+	}
+	`
+
+	bytecode := parseAndCompile(t, input)
+
+	// Should have IS_EQUAL and JMPNZ opcodes for case comparisons
+	hasIsEqual := false
+	hasJmpnz := false
+
+	for _, instr := range bytecode.Instructions {
+		if instr.Opcode == vm.OpIsEqual {
+			hasIsEqual = true
+		}
+		if instr.Opcode == vm.OpJmpNZ {
+			hasJmpnz = true
+		}
+	}
+
+	if !hasIsEqual {
+		t.Error("Expected IS_EQUAL instruction for switch cases")
+	}
+	if !hasJmpnz {
+		t.Error("Expected JMPNZ instruction for switch cases")
+	}
+}
+
+func TestCompileSwitchJumpTable(t *testing.T) {
+	input := `<?php
+	switch ($x) {
+		case "a":
+			echo "a";
+		case "b":
+			echo "b";
+			break;
+		default:
+			echo "other";
+	}
+	`
+
+	bytecode := parseAndCompile(t, input)
+
+	instr, ok := findOpcode(bytecode.Instructions, vm.OpSwitchString)
+	if !ok {
+		t.Fatal("Expected SWITCH_STRING for non-numeric string labels")
+	}
+	table := bytecode.Constants[instr.Op2.Value].(*vm.JumpTable)
+	if len(table.Strings) != 2 {
+		t.Fatalf("Expected 2 jump table entries, got %d", len(table.Strings))
+	}
+	if table.Strings["a"] >= table.Strings["b"] || table.Strings["b"] >= table.Default {
+		t.Errorf("Jump table targets out of order: %+v", table)
+	}
+	if bytecode.Instructions[table.Strings["a"]].Opcode != vm.OpQMAssign {
+		t.Errorf("Case \"a\" should jump to its body, got %s", bytecode.Instructions[table.Strings["a"]].Opcode)
+	}
+}
+
+func TestCompileSwitchNumericStringsUseComparisons(t *testing.T) {
+	input := `<?php
+	switch ($x) {
+		case "1":
+			break;
+		case "abc":
+			break;
+	}
+	`
+
+	bytecode := parseAndCompile(t, input)
+	if _, ok := findOpcode(bytecode.Instructions, vm.OpSwitchString); ok {
+		t.Error("Numeric string labels compare loosely and must not use SWITCH_STRING")
+	}
+}
+
+func TestCompileMatchExpression(t *testing.T) {
+	input := `<?php
+	$r = match ($x) {
+		$a, $b => 1,
+		default => 2,
+	};
+	`
+
+	bytecode := parseAndCompile(t, input)
+
+	caseStrict := 0
 	for _, instr := range bytecode.Instructions {
-		if instr.Opcode == vm.OpJmp {
-			jmpCount++
+		if instr.Opcode == vm.OpCaseStrict {
+			caseStrict++
+		}
+		if instr.Opcode == vm.OpIsEqual {
+			t.Error("match must compare strictly, found IS_EQUAL")
 		}
 	}
-
-	if jmpCount < 3 {
-		t.Errorf("Expected at least 3 JMP instructions (if-end, continue, loop), got %d", jmpCount)
+	if caseStrict != 2 {
+		t.Errorf("Expected 2 CASE_STRICT instructions, got %d", caseStrict)
+	}
+	if _, ok := findOpcode(bytecode.Instructions, vm.OpMatchError); ok {
+		t.Error("A match with a default arm should not emit MATCH_ERROR")
 	}
 }
 
-func TestCompileSwitchStatement(t *testing.T) {
+func TestCompileMatchJumpTable(t *testing.T) {
 	input := `<?php
-	switch ($x) {
-		case 1:
-			echo "one";
-			break;
-		case 2:
-			echo "two";
-			break;
-		default:
-			echo "other";
-	}
+	$r = match ($x) {
+		1, 2 => "low",
+		3, 4, 5 => "high",
+	};
 	`
 
 	bytecode := parseAndCompile(t, input)
 
-	// Should have IS_EQUAL and JMPNZ opcodes for case comparisons
-	hasIsEqual := false
-	hasJmpnz := false
+	instr, ok := findOpcode(bytecode.Instructions, vm.OpMatch)
+	if !ok {
+		t.Fatal("Expected MATCH for integer literal conditions")
+	}
+	table := bytecode.Constants[instr.Op2.Value].(*vm.JumpTable)
+	if len(table.Longs) != 5 || table.Longs[1] != table.Longs[2] || table.Longs[3] != table.Longs[5] {
+		t.Errorf("Unexpected jump table: %+v", table)
+	}
+	if bytecode.Instructions[table.Default].Opcode != vm.OpMatchError {
+		t.Errorf("Default target should be MATCH_ERROR, got %s", bytecode.Instructions[table.Default].Opcode)
+	}
+}
 
-	for _, instr := range bytecode.Instructions {
-		if instr.Opcode == vm.OpIsEqual {
-			hasIsEqual = true
+// findOpcode returns the first instruction with the given opcode
+func findOpcode(instructions vm.Instructions, opcode vm.Opcode) (vm.Instruction, bool) {
+	for _, instr := range instructions {
+		if instr.Opcode == opcode {
+			return instr, true
 		}
-		if instr.Opcode == vm.OpJmpNZ {
-			hasJmpnz = true
+	}
+	return vm.Instruction{}, false
+}
+
+func TestCompileIncludeExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		kind  uint32
+	}{
+		{`<?php include "a.php";`, vm.IncludeKindInclude},
+		{`<?php include_once "a.php";`, vm.IncludeKindIncludeOnce},
+		{`<?php require "a.php";`, vm.IncludeKindRequire},
+		{`<?php require_once "a.php";`, vm.IncludeKindRequireOnce},
+	}
+
+	for _, tt := range tests {
+		bytecode := parseAndCompile(t, tt.input)
+
+		found := false
+		for _, instr := range bytecode.Instructions {
+			if instr.Opcode == vm.OpIncludeOrEval {
+				found = true
+				if instr.ExtendedValue != tt.kind {
+					t.Errorf("%s: expected include kind %d, got %d", tt.input, tt.kind, instr.ExtendedValue)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%s: expected INCLUDE_OR_EVAL instruction", tt.input)
 		}
 	}
+}
 
-	if !hasIsEqual {
-		t.Error("Expected IS_EQUAL instruction for switch cases")
+func TestCompileScript(t *testing.T) {
+	script, err := CompileScript("lib.php", []byte(`<?php $a = 1; $b = $a;`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	if !hasJmpnz {
-		t.Error("Expected JMPNZ instruction for switch cases")
+
+	if script.Path != "lib.php" {
+		t.Errorf("Expected path lib.php, got %s", script.Path)
+	}
+	if len(script.Variables) != 2 || script.Variables[0] != "a" || script.Variables[1] != "b" {
+		t.Errorf("Expected variables [a b], got %v", script.Variables)
+	}
+
+	if _, err := CompileScript("bad.php", []byte(`<?php $a = ;`)); err == nil {
+		t.Error("Expected parse error")
 	}
 }
 
@@ -1502,7 +2440,7 @@ func TestCompileFunctionDeclaration(t *testing.T) {
 	hasDeclareFunc := false
 	hasEcho := false
 
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpDeclareFunction {
 			hasDeclareFunc = true
 		}
@@ -1520,7 +2458,7 @@ func TestCompileFunctionDeclaration(t *testing.T) {
 
 	// Should have function name as constant
 	foundGreet := false
-	for _, c := range bytecode.Constants {
+	for _, c := range allConstants(bytecode) {
 		if s, ok := c.(string); ok && s == "greet" {
 			foundGreet = true
 			break
@@ -1531,6 +2469,53 @@ func TestCompileFunctionDeclaration(t *testing.T) {
 	}
 }
 
+func TestCompileFunctionOpArrays(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+function f($n) { while ($n) { $n--; } return "body"; }
+echo "main";`)
+
+	// The main op array only declares the function
+	for _, instr := range bytecode.Instructions {
+		switch instr.Opcode {
+		case vm.OpRecv, vm.OpReturn, vm.OpJmp:
+			t.Errorf("Main op array contains %s from the function body", instr.Opcode)
+		}
+	}
+	decl, ok := bytecode.Constants[bytecode.Instructions[0].Op1.Value].(*vm.FunctionDecl)
+	if bytecode.Instructions[0].Opcode != vm.OpDeclareFunction || !ok {
+		t.Fatalf("Expected DECLARE_FUNCTION of a function decl, got %s", bytecode.Instructions[0].Opcode)
+	}
+
+	if len(bytecode.Functions) != 1 || bytecode.Functions[0] != decl.Function {
+		t.Fatalf("Expected the declared function in the function table, got %v", bytecode.Functions)
+	}
+	fn := decl.Function
+	if fn.Instructions[0].Opcode != vm.OpRecv {
+		t.Errorf("Function op array should start with RECV, got %s", fn.Instructions[0].Opcode)
+	}
+
+	// Jumps target positions within the function's own op array
+	for _, instr := range fn.Instructions {
+		if instr.Opcode == vm.OpJmp && int(instr.Op1.Value) >= len(fn.Instructions) {
+			t.Errorf("Jump target %d outside the function's %d instructions", instr.Op1.Value, len(fn.Instructions))
+		}
+	}
+
+	// Each op array has its own constants
+	for _, c := range bytecode.Constants {
+		if c == "body" {
+			t.Error("Function constant found in the main constant table")
+		}
+	}
+	found := false
+	for _, c := range fn.Constants {
+		found = found || c == "body"
+	}
+	if !found {
+		t.Error("Expected the function's constant in its own table")
+	}
+}
+
 func TestCompileFunctionWithParameters(t *testing.T) {
 	input := `<?php
 	function add($a, $b) {
@@ -1545,7 +2530,7 @@ func TestCompileFunctionWithParameters(t *testing.T) {
 	hasAdd := false
 	hasReturn := false
 
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpRecv {
 			recvCount++
 		}
@@ -1579,7 +2564,7 @@ func TestCompileFunctionWithDefaultParameter(t *testing.T) {
 
 	// Should have RECV_INIT opcode for parameter with default
 	hasRecvInit := false
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpRecvInit {
 			hasRecvInit = true
 			break
@@ -1592,7 +2577,7 @@ func TestCompileFunctionWithDefaultParameter(t *testing.T) {
 
 	// Should have "World" as constant for default value
 	foundWorld := false
-	for _, c := range bytecode.Constants {
+	for _, c := range allConstants(bytecode) {
 		if s, ok := c.(string); ok && s == "World" {
 			foundWorld = true
 			break
@@ -1614,7 +2599,7 @@ func TestCompileFunctionWithVariadicParameter(t *testing.T) {
 
 	// Should have RECV_VARIADIC opcode
 	hasRecvVariadic := false
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpRecvVariadic {
 			hasRecvVariadic = true
 			break
@@ -1639,7 +2624,7 @@ func TestCompileFunctionWithReturnValue(t *testing.T) {
 	hasMul := false
 	hasReturn := false
 
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpMul {
 			hasMul = true
 		}
@@ -1667,7 +2652,7 @@ func TestCompileFunctionWithMultipleParameters(t *testing.T) {
 
 	// Should have 3 RECV opcodes
 	recvCount := 0
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpRecv {
 			recvCount++
 		}
@@ -1689,7 +2674,7 @@ func TestCompileFunctionImplicitReturn(t *testing.T) {
 
 	// Should have implicit RETURN at end
 	hasReturn := false
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpReturn {
 			hasReturn = true
 			break
@@ -1762,7 +2747,7 @@ func TestCompileFunctionWithMixedParameters(t *testing.T) {
 	hasRecvInit := false
 	hasRecvVariadic := false
 
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpRecv {
 			hasRecv = true
 		}
@@ -1802,7 +2787,7 @@ func TestCompileFunctionWithComplexBody(t *testing.T) {
 	hasJmpz := false
 	returnCount := 0
 
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpJmpZ {
 			hasJmpz = true
 		}
@@ -1884,18 +2869,27 @@ class User {
 		}
 	}
 
-	// Should have default value "default@example.com" in constants
-	hasDefaultValue := false
-	for _, c := range bytecode.Constants {
-		if str, ok := c.(string); ok && str == "default@example.com" {
-			hasDefaultValue = true
-			break
-		}
+	// Should have default value "default@example.com" in the class declaration
+	decl := findClassDecl(t, bytecode, "User")
+	email, ok := decl.Class.Properties["email"]
+	if !ok || !email.HasDefault || email.Default.ToString() != "default@example.com" {
+		t.Error("Expected default value 'default@example.com' for property email")
 	}
+	if password := decl.Class.Properties["password"]; password == nil || password.Visibility != types.VisibilityPrivate {
+		t.Error("Expected private property password")
+	}
+}
 
-	if !hasDefaultValue {
-		t.Error("Expected default value 'default@example.com' in constants")
+// findClassDecl returns the declaration of a class in the constant pool
+func findClassDecl(t *testing.T, bytecode *Bytecode, name string) *vm.ClassDecl {
+	t.Helper()
+	for _, c := range bytecode.Constants {
+		if decl, ok := c.(*vm.ClassDecl); ok && decl.Class.Name == name {
+			return decl
+		}
 	}
+	t.Fatalf("Expected a declaration of %s", name)
+	return nil
 }
 
 func TestCompileClassWithMethod(t *testing.T) {
@@ -1911,7 +2905,7 @@ class User {
 
 	// Should have method name "getName" in constants
 	hasMethodName := false
-	for _, c := range bytecode.Constants {
+	for _, c := range allConstants(bytecode) {
 		if str, ok := c.(string); ok && str == "getName" {
 			hasMethodName = true
 			break
@@ -1924,7 +2918,7 @@ class User {
 
 	// Should have RETURN opcode for method
 	hasReturn := false
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpReturn {
 			hasReturn = true
 			break
@@ -1951,7 +2945,7 @@ class User {
 
 	// Should have constructor name "__construct" in constants
 	hasConstructor := false
-	for _, c := range bytecode.Constants {
+	for _, c := range allConstants(bytecode) {
 		if str, ok := c.(string); ok && str == "__construct" {
 			hasConstructor = true
 			break
@@ -1964,7 +2958,7 @@ class User {
 
 	// Should have RECV opcode for parameter
 	hasRecv := false
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpRecv {
 			hasRecv = true
 			break
@@ -2040,7 +3034,7 @@ class Calculator {
 	methodNames := []string{"add", "subtract", "multiply"}
 	for _, methodName := range methodNames {
 		found := false
-		for _, c := range bytecode.Constants {
+		for _, c := range allConstants(bytecode) {
 			if str, ok := c.(string); ok && str == methodName {
 				found = true
 				break
@@ -2053,7 +3047,7 @@ class Calculator {
 
 	// Should have RECV opcodes for parameters (2 parameters * 3 methods = 6)
 	recvCount := 0
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpRecv {
 			recvCount++
 		}
@@ -2065,7 +3059,7 @@ class Calculator {
 
 	// Should have RETURN opcodes for methods (3 methods)
 	returnCount := 0
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpReturn {
 			returnCount++
 		}
@@ -2089,7 +3083,7 @@ class User {
 
 	// Should have RECV for required parameter
 	hasRecv := false
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpRecv {
 			hasRecv = true
 			break
@@ -2102,7 +3096,7 @@ class User {
 
 	// Should have RECV_INIT for optional parameter
 	hasRecvInit := false
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpRecvInit {
 			hasRecvInit = true
 			break
@@ -2115,7 +3109,7 @@ class User {
 
 	// Should have default value "Hello" in constants
 	hasDefaultValue := false
-	for _, c := range bytecode.Constants {
+	for _, c := range allConstants(bytecode) {
 		if str, ok := c.(string); ok && str == "Hello" {
 			hasDefaultValue = true
 			break
@@ -2150,7 +3144,7 @@ class Account {
 
 	// Should have DECLARE_CLASS opcode
 	hasDeclareClass := false
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpDeclareClass {
 			hasDeclareClass = true
 			break
@@ -2163,7 +3157,7 @@ class Account {
 
 	// Should have JMPZ for if statement
 	hasJmpz := false
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpJmpZ {
 			hasJmpz = true
 			break
@@ -2176,7 +3170,7 @@ class Account {
 
 	// Should have multiple RETURN opcodes
 	returnCount := 0
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpReturn {
 			returnCount++
 		}
@@ -2250,7 +3244,7 @@ class Logger {
 
 	// Should have RECV for required parameter
 	hasRecv := false
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpRecv {
 			hasRecv = true
 			break
@@ -2263,7 +3257,7 @@ class Logger {
 
 	// Should have RECV_VARIADIC for variadic parameter
 	hasRecvVariadic := false
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpRecvVariadic {
 			hasRecvVariadic = true
 			break
@@ -2276,7 +3270,7 @@ class Logger {
 
 	// Should have foreach opcodes
 	hasFeFetch := false
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpFeFetchR || instr.Opcode == vm.OpFeFetchRW {
 			hasFeFetch = true
 			break
@@ -3077,7 +4071,7 @@ function test() {
 
 	// Should have constant 3 (from 1+2 folding)
 	hasThree := false
-	for _, c := range bytecode.Constants {
+	for _, c := range allConstants(bytecode) {
 		if i, ok := c.(int64); ok && i == 3 {
 			hasThree = true
 			break
@@ -3089,7 +4083,7 @@ function test() {
 	}
 
 	// Should NOT have ADD opcode (it was folded)
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpAdd {
 			t.Error("Found ADD opcode - constant folding didn't work")
 		}
@@ -3097,7 +4091,7 @@ function test() {
 
 	// Should have MUL opcode (variable operation with non-power-of-2)
 	hasMul := false
-	for _, instr := range bytecode.Instructions {
+	for _, instr := range allInstructions(bytecode) {
 		if instr.Opcode == vm.OpMul {
 			hasMul = true
 			break
@@ -3409,3 +4403,299 @@ func TestGetConstantMethod(t *testing.T) {
 		t.Error("Expected GetConstant to return error for invalid index")
 	}
 }
+
+func TestCompileFirstClassCallable(t *testing.T) {
+	tests := []struct {
+		input    string
+		initCall vm.Opcode
+	}{
+		{`<?php $f = strlen(...);`, vm.OpInitFcallByName},
+		{`<?php $f = $obj->method(...);`, vm.OpInitMethodCall},
+		{`<?php $f = Foo::bar(...);`, vm.OpInitStaticMethodCall},
+	}
+
+	for _, tt := range tests {
+		bytecode := parseAndCompile(t, tt.input)
+
+		convertPos := -1
+		for i, instr := range bytecode.Instructions {
+			if instr.Opcode == vm.OpDoFcall {
+				t.Errorf("%s: first-class callable must not emit DO_FCALL", tt.input)
+			}
+			if instr.Opcode == vm.OpCallableConvert {
+				convertPos = i
+			}
+		}
+
+		if convertPos < 1 {
+			t.Fatalf("%s: expected CALLABLE_CONVERT instruction", tt.input)
+		}
+		if bytecode.Instructions[convertPos-1].Opcode != tt.initCall {
+			t.Errorf("%s: expected %s before CALLABLE_CONVERT, got %s",
+				tt.input, tt.initCall, bytecode.Instructions[convertPos-1].Opcode)
+		}
+	}
+}
+
+func TestCompileStaticVarStatement(t *testing.T) {
+	input := `<?php
+	static $count = 0, $cache;
+	`
+
+	bytecode := parseAndCompile(t, input)
+
+	initIdx := -1
+	var binds []int
+	for i, instr := range bytecode.Instructions {
+		switch instr.Opcode {
+		case vm.OpBindInitStaticOrJmp:
+			initIdx = i
+		case vm.OpBindStatic:
+			binds = append(binds, i)
+		}
+	}
+	if initIdx < 0 {
+		t.Fatal("Expected BIND_INIT_STATIC_OR_JMP for the initialized static")
+	}
+	if len(binds) != 2 {
+		t.Fatalf("Expected 2 BIND_STATIC instructions, got %d", len(binds))
+	}
+
+	// The initialized static jumps past its BIND_STATIC once initialized
+	init := bytecode.Instructions[initIdx]
+	if int(init.Op2.Value) != binds[0]+1 {
+		t.Errorf("Expected jump target %d, got %d", binds[0]+1, init.Op2.Value)
+	}
+	if bytecode.Instructions[binds[1]].Op2.Type != vm.OpUnused {
+		t.Error("A static without initializer should bind null (unused operand)")
+	}
+}
+
+func TestCompileGlobalStatement(t *testing.T) {
+	input := `<?php
+	global $config;
+	$all = $GLOBALS;
+	`
+
+	bytecode := parseAndCompile(t, input)
+
+	instr, ok := findOpcode(bytecode.Instructions, vm.OpBindGlobal)
+	if !ok {
+		t.Fatal("Expected BIND_GLOBAL for global $config")
+	}
+	if instr.Op1.Type != vm.OpCV || instr.Op2.Type != vm.OpConst {
+		t.Errorf("Expected BIND_GLOBAL CV, CONST; got %v, %v", instr.Op1.Type, instr.Op2.Type)
+	}
+	if name := bytecode.Constants[instr.Op2.Value]; name != "config" {
+		t.Errorf("Expected global name 'config', got %v", name)
+	}
+	if _, ok := findOpcode(bytecode.Instructions, vm.OpFetchGlobals); !ok {
+		t.Error("Expected FETCH_GLOBALS for $GLOBALS")
+	}
+	for _, name := range bytecode.Variables {
+		if name == "GLOBALS" {
+			t.Error("$GLOBALS should not be a compiled variable")
+		}
+	}
+}
+
+func TestCompileCompoundAssignment(t *testing.T) {
+	tests := []struct {
+		input  string
+		opcode vm.Opcode
+		binary vm.Opcode
+	}{
+		{`<?php $x += 1;`, vm.OpAssignOp, vm.OpAdd},
+		{`<?php $s .= "a";`, vm.OpAssignOp, vm.OpConcat},
+		{`<?php $x <<= 2;`, vm.OpAssignOp, vm.OpSL},
+		{`<?php $a["k"] -= 1;`, vm.OpAssignDimOp, vm.OpSub},
+		{`<?php $o->count *= 2;`, vm.OpAssignObjOp, vm.OpMul},
+	}
+
+	for _, tt := range tests {
+		bytecode := parseAndCompile(t, tt.input)
+		instr, ok := findOpcode(bytecode.Instructions, tt.opcode)
+		if !ok {
+			t.Errorf("%s: expected %s", tt.input, tt.opcode)
+			continue
+		}
+		if vm.Opcode(instr.ExtendedValue) != tt.binary {
+			t.Errorf("%s: expected extended value %s, got %s", tt.input, tt.binary, vm.Opcode(instr.ExtendedValue))
+		}
+		if _, ok := findOpcode(bytecode.Instructions, vm.OpAssign); ok {
+			t.Errorf("%s: compound assignment should not emit a plain ASSIGN", tt.input)
+		}
+	}
+}
+
+func TestCompileIncDec(t *testing.T) {
+	tests := []struct {
+		input  string
+		opcode vm.Opcode
+	}{
+		{`<?php ++$i;`, vm.OpPreInc},
+		{`<?php --$i;`, vm.OpPreDec},
+		{`<?php $i++;`, vm.OpPostInc},
+		{`<?php $i--;`, vm.OpPostDec},
+		{`<?php ++$o->n;`, vm.OpPreIncObj},
+		{`<?php $o->n--;`, vm.OpPostDecObj},
+	}
+
+	for _, tt := range tests {
+		bytecode := parseAndCompile(t, tt.input)
+		if _, ok := findOpcode(bytecode.Instructions, tt.opcode); !ok {
+			t.Errorf("%s: expected %s", tt.input, tt.opcode)
+		}
+	}
+}
+
+func TestCompileInterpolatedString(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php "Hello $name and {$arr['k']}!";`)
+
+	var ropes []vm.Instruction
+	for _, instr := range bytecode.Instructions {
+		switch instr.Opcode {
+		case vm.OpRopeInit, vm.OpRopeAdd, vm.OpRopeEnd:
+			ropes = append(ropes, instr)
+		case vm.OpConcat:
+			t.Error("Expected a rope instead of CONCAT")
+		}
+	}
+
+	expected := []vm.Opcode{vm.OpRopeInit, vm.OpRopeAdd, vm.OpRopeAdd, vm.OpRopeAdd, vm.OpRopeEnd}
+	if len(ropes) != len(expected) {
+		t.Fatalf("Expected %d rope instructions, got %d", len(expected), len(ropes))
+	}
+	for i, instr := range ropes {
+		if instr.Opcode != expected[i] {
+			t.Errorf("Instruction %d: expected %s, got %s", i, expected[i], instr.Opcode)
+		}
+	}
+
+	if ropes[0].ExtendedValue != 5 {
+		t.Errorf("Expected ROPE_INIT with 5 parts, got %d", ropes[0].ExtendedValue)
+	}
+	if ropes[0].Op2.Type != vm.OpConst || bytecode.Constants[ropes[0].Op2.Value] != "Hello " {
+		t.Errorf("Expected ROPE_INIT of constant 'Hello ', got %v", ropes[0].Op2)
+	}
+	if ropes[1].Op2.Type != vm.OpTmpVar {
+		t.Errorf("Expected ROPE_ADD of the compiled $name, got %v", ropes[1].Op2)
+	}
+	if _, ok := findOpcode(bytecode.Instructions, vm.OpFetchDimR); !ok {
+		t.Error("Expected FETCH_DIM_R for {$arr['k']}")
+	}
+	if end := ropes[4]; end.Op1 != ropes[0].Result || end.Result.Type != vm.OpTmpVar || end.Result.Value != 0 {
+		t.Errorf("Expected ROPE_END of the rope into TmpVar(0), got %v -> %v", end.Op1, end.Result)
+	}
+}
+
+func TestCompileNestedInterpolatedString(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php "a {$m["b$c"]} d";`)
+
+	var inits []vm.Instruction
+	for _, instr := range bytecode.Instructions {
+		if instr.Opcode == vm.OpRopeInit {
+			inits = append(inits, instr)
+		}
+	}
+
+	if len(inits) != 2 {
+		t.Fatalf("Expected 2 ROPE_INIT instructions, got %d", len(inits))
+	}
+	if inits[0].Result == inits[1].Result {
+		t.Errorf("Expected the nested rope in its own temporary, both use %v", inits[0].Result)
+	}
+}
+
+func TestCompileSingleInterpolation(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php "$n";`)
+
+	init, ok := findOpcode(bytecode.Instructions, vm.OpRopeInit)
+	if !ok {
+		t.Fatal("Expected ROPE_INIT for \"$n\"")
+	}
+	end, ok := findOpcode(bytecode.Instructions, vm.OpRopeEnd)
+	if !ok {
+		t.Fatal("Expected ROPE_END converting $n to a string")
+	}
+	if init.ExtendedValue != 1 || end.Op2.Type != vm.OpUnused {
+		t.Errorf("Expected a single-part rope, got %d parts and ROPE_END %v", init.ExtendedValue, end.Op2)
+	}
+}
+
+func TestCompileClosureThis(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+$f = function () { return $this->name; };
+$g = static fn() => $this;
+`)
+
+	// Outside of methods $this is fetched from the closure's binding
+	closures := 0
+	for _, c := range bytecode.Constants {
+		decl, ok := c.(*vm.FunctionDecl)
+		if !ok {
+			continue
+		}
+		closures++
+		if decl.Function.Instructions[0].Opcode != vm.OpFetchThis {
+			t.Errorf("Expected FETCH_THIS, got %s", decl.Function.Instructions[0].Opcode)
+		}
+		if len(decl.Function.Variables) != 0 {
+			t.Errorf("Expected no $this variable, got %v", decl.Function.Variables)
+		}
+	}
+	if closures != 2 {
+		t.Errorf("Expected 2 closures, got %d", closures)
+	}
+	if flags := bytecode.Instructions[len(bytecode.Instructions)-3].ExtendedValue; flags&vm.LambdaStatic == 0 {
+		t.Errorf("Expected a static closure, got flags %d", flags)
+	}
+}
+
+func TestConstantFoldingOverflow(t *testing.T) {
+	// Integer results that overflow or are not whole are folded to floats
+	input := `<?php
+$x = 9223372036854775807 + 1;
+$y = 7 / 2;
+$z = 3 ** 41;
+`
+
+	bytecode := parseAndCompile(t, input)
+
+	for _, expected := range []float64{9223372036854775808, 3.5, 36472996377170786403} {
+		found := false
+		for _, c := range bytecode.Constants {
+			if f, ok := c.(float64); ok && f == expected {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected folded float constant %v in constant pool, got %v", expected, bytecode.Constants)
+		}
+	}
+}
+
+func TestCompileDimAssignment(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php $s = "abc"; $s[-1] = "x";`)
+
+	instr, ok := findOpcode(bytecode.Instructions, vm.OpAssignDim)
+	if !ok {
+		t.Fatal("Expected ASSIGN_DIM")
+	}
+	// The key is kept out of temp 0, where the value is compiled
+	if instr.Op1.Type != vm.OpCV || !instr.Op2.IsTmpVar() || instr.Op2 == vm.TmpVarOperand(0) || instr.Result != vm.TmpVarOperand(0) {
+		t.Errorf("Expected ASSIGN_DIM $s, T1, T0, got %v", instr)
+	}
+
+	// Nested elements are written through the fetched inner array
+	bytecode = parseAndCompile(t, `<?php $a[0][1] = 2;`)
+	fetch, ok := findOpcode(bytecode.Instructions, vm.OpFetchDimW)
+	if !ok {
+		t.Fatal("Expected FETCH_DIM_W")
+	}
+	instr, ok = findOpcode(bytecode.Instructions, vm.OpAssignDim)
+	if !ok || instr.Op1 != fetch.Result {
+		t.Errorf("Expected ASSIGN_DIM into the result of FETCH_DIM_W, got %v", instr)
+	}
+}
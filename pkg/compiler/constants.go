@@ -0,0 +1,161 @@
+package compiler
+
+import (
+	"path/filepath"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/lexer"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// Constants
+// ========================================
+
+// magicScope names the class and function being compiled, which the magic
+// constants __CLASS__, __TRAIT__, __FUNCTION__ and __METHOD__ evaluate to
+type magicScope struct {
+	class    string // Fully qualified class, trait or enum name
+	trait    bool   // Whether class names a trait
+	function string // Function or method name, "{closure}" in closures
+	method   string // Class::method in methods, the function name otherwise
+}
+
+// SetFile sets the path of the script being compiled, which __FILE__ and
+// __DIR__ evaluate to
+func (c *Compiler) SetFile(path string) {
+	c.file = path
+}
+
+// enterClass starts compiling the body of a class-like declaration. It
+// returns the enclosing scope, to be restored when the body is compiled.
+func (c *Compiler) enterClass(name string, trait bool) magicScope {
+	outer := c.scope
+	c.scope = magicScope{class: name, trait: trait}
+	return outer
+}
+
+// enterFunctionScope starts compiling a function, method or closure body
+// for the magic constants. It returns the enclosing scope.
+func (c *Compiler) enterFunctionScope(function string) magicScope {
+	outer := c.scope
+	c.scope.function = function
+	c.scope.method = function
+	if c.scope.class != "" && function != "{closure}" {
+		c.scope.method = c.scope.class + "::" + function
+	}
+	return outer
+}
+
+// compileConstantFetch compiles a use of a global constant into temp 0.
+// Unqualified names inside a namespace fall back to the global constant.
+func (c *Compiler) compileConstantFetch(node *ast.Identifier) error {
+	name, fallback := c.namespace.ResolveConstantName(node.Value)
+	fallbackOperand := vm.UnusedOperand()
+	if fallback != "" {
+		fallbackOperand = vm.ConstOperand(uint32(c.AddConstant(fallback)))
+	}
+	c.EmitWithLine(vm.OpFetchConstant, uint32(node.Token.Pos.Line),
+		vm.ConstOperand(uint32(c.AddConstant(name))),
+		fallbackOperand,
+		vm.TmpVarOperand(0))
+	return nil
+}
+
+// compileMemberName compiles the name of a property or method into temp 0:
+// an identifier is the name itself, anything else is evaluated
+func (c *Compiler) compileMemberName(expr ast.Expr) error {
+	ident, ok := expr.(*ast.Identifier)
+	if !ok {
+		return c.Compile(expr)
+	}
+	c.EmitWithLine(vm.OpQMAssign, uint32(ident.Token.Pos.Line),
+		vm.ConstOperand(uint32(c.AddConstant(ident.Value))),
+		vm.UnusedOperand(),
+		vm.TmpVarOperand(0))
+	return nil
+}
+
+// compileMagicConstant compiles a magic constant, resolved at compile time,
+// into temp 0. __CLASS__ in a trait is the class using the trait, so it is
+// resolved at runtime.
+func (c *Compiler) compileMagicConstant(node *ast.MagicConstant) error {
+	line := uint32(node.Token.Pos.Line)
+
+	var value interface{}
+	switch node.Token.Type {
+	case lexer.LINE_CONST:
+		value = int64(node.Token.Pos.Line)
+	case lexer.FILE_CONST:
+		value = c.file
+	case lexer.DIR_CONST:
+		value = ""
+		if c.file != "" {
+			value = filepath.Dir(c.file)
+		}
+	case lexer.FUNCTION_CONST:
+		value = c.scope.function
+	case lexer.METHOD_CONST:
+		value = c.scope.method
+	case lexer.NAMESPACE_CONST:
+		value = c.namespace.Name()
+	case lexer.TRAIT_CONST:
+		value = ""
+		if c.scope.trait {
+			value = c.scope.class
+		}
+	case lexer.CLASS_CONST:
+		if c.scope.trait {
+			c.EmitWithLine(vm.OpFetchClassName, line,
+				vm.ConstOperand(uint32(c.AddConstant("self"))),
+				vm.UnusedOperand(),
+				vm.TmpVarOperand(0))
+			return nil
+		}
+		value = c.scope.class
+	}
+
+	c.EmitWithLine(vm.OpQMAssign, line,
+		vm.ConstOperand(uint32(c.AddConstant(value))),
+		vm.UnusedOperand(),
+		vm.TmpVarOperand(0))
+	return nil
+}
+
+// compileConstStatement compiles a top-level const declaration into a
+// DECLARE_CONST per constant. Constant expressions are folded; other
+// initializers are evaluated first.
+func (c *Compiler) compileConstStatement(node *ast.ConstStatement) error {
+	for _, item := range node.Constants {
+		line := uint32(item.Name.Token.Pos.Line)
+		name := vm.ConstOperand(uint32(c.AddConstant(c.namespace.prefix(item.Name.Value))))
+
+		value := vm.TmpVarOperand(0)
+		if folded, ok := constantExpressionValue(item.Value); ok {
+			value = vm.ConstOperand(uint32(c.AddConstant(folded)))
+		} else if err := c.Compile(item.Value); err != nil {
+			return err
+		}
+		c.EmitWithLine(vm.OpDeclareConst, line, name, value, vm.UnusedOperand())
+	}
+	return nil
+}
+
+// resolveClassName resolves a class name like the namespace does. In the
+// deferred initializers of a class, which run outside of its scope, self
+// and static name the class itself and parent its parent.
+func (c *Compiler) resolveClassName(name string) string {
+	name = c.namespace.ResolveClassName(name)
+	if c.initializer == nil {
+		return name
+	}
+	switch name {
+	case "self", "static":
+		return c.initializer.Class.Name
+	case "parent":
+		if c.initializer.Parent != "" {
+			return c.initializer.Parent
+		}
+	}
+	return name
+}
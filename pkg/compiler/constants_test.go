@@ -0,0 +1,161 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/lexer"
+	"github.com/krizos/php-go/pkg/parser"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+func TestCompileMagicConstants(t *testing.T) {
+	input := `<?php
+namespace App;
+echo __LINE__, __NAMESPACE__, __FILE__, __DIR__;
+function f() { echo __FUNCTION__, __METHOD__; }
+class C {
+	function m() {
+		echo __CLASS__, __FUNCTION__, __METHOD__;
+		$g = function () { echo __FUNCTION__, __CLASS__; };
+	}
+}
+trait T { function t() { echo __TRAIT__, __CLASS__; } }
+echo __CLASS__, __FUNCTION__;`
+
+	p := parser.New(lexer.New(input, "/src/app/index.php"))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parser errors:\n%v", p.Errors())
+	}
+	c := New()
+	c.SetFile("/src/app/index.php")
+	if err := c.Compile(program); err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	bytecode := c.Bytecode()
+
+	// The values echoed by the main op array, then by each op array of the
+	// function table; a trait's __CLASS__ is fetched at runtime
+	var echoed []interface{}
+	collect := func(instructions vm.Instructions, constants []interface{}) {
+		for i, instr := range instructions {
+			if instr.Opcode != vm.OpEcho || i == 0 {
+				continue
+			}
+			prev := instructions[i-1]
+			switch prev.Opcode {
+			case vm.OpQMAssign:
+				echoed = append(echoed, constants[prev.Op1.Value])
+			case vm.OpFetchClassName:
+				echoed = append(echoed, "FETCH_CLASS_NAME")
+			}
+		}
+	}
+	collect(bytecode.Instructions, bytecode.Constants)
+	for _, fn := range bytecode.Functions {
+		collect(fn.Instructions, fn.Constants)
+	}
+
+	expected := []interface{}{
+		int64(3), "App", "/src/app/index.php", "/src/app",
+		"", "",
+		"App\\f", "App\\f",
+		"App\\C", "m", "App\\C::m",
+		"{closure}", "App\\C",
+		"App\\T", "FETCH_CLASS_NAME",
+	}
+	if len(echoed) != len(expected) {
+		t.Fatalf("Expected %d echoed values, got %d: %v", len(expected), len(echoed), echoed)
+	}
+	for i := range expected {
+		if echoed[i] != expected[i] {
+			t.Errorf("Value %d: expected %v, got %v", i, expected[i], echoed[i])
+		}
+	}
+}
+
+func TestCompileConstantFetch(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+namespace App;
+echo FOO, \BAR, Sub\BAZ;`)
+
+	var fetches []string
+	for _, instr := range bytecode.Instructions {
+		if instr.Opcode != vm.OpFetchConstant {
+			continue
+		}
+		fetch := bytecode.Constants[instr.Op1.Value].(string)
+		if instr.Op2.Type == vm.OpConst {
+			fetch += " or " + bytecode.Constants[instr.Op2.Value].(string)
+		}
+		fetches = append(fetches, fetch)
+	}
+
+	expected := []string{"App\\FOO or FOO", "BAR", "App\\Sub\\BAZ"}
+	if len(fetches) != len(expected) {
+		t.Fatalf("Expected FETCH_CONSTANT %v, got %v", expected, fetches)
+	}
+	for i := range expected {
+		if fetches[i] != expected[i] {
+			t.Errorf("Fetch %d: expected %q, got %q", i, expected[i], fetches[i])
+		}
+	}
+}
+
+func TestCompileConstStatement(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+namespace App;
+const A = 2 * 3, B = [A];`)
+
+	var declares []vm.Instruction
+	for _, instr := range bytecode.Instructions {
+		if instr.Opcode == vm.OpDeclareConst {
+			declares = append(declares, instr)
+		}
+	}
+	if len(declares) != 2 {
+		t.Fatalf("Expected 2 DECLARE_CONST, got %d", len(declares))
+	}
+
+	// A is folded, B's array refers to A and is evaluated at runtime
+	if name := bytecode.Constants[declares[0].Op1.Value]; name != "App\\A" {
+		t.Errorf("Expected constant App\\A, got %v", name)
+	}
+	if declares[0].Op2.Type != vm.OpConst || bytecode.Constants[declares[0].Op2.Value] != int64(6) {
+		t.Errorf("Expected A to be the folded constant 6")
+	}
+	if declares[1].Op2.Type != vm.OpTmpVar {
+		t.Errorf("Expected B's value to be evaluated into a temporary")
+	}
+}
+
+func TestCompileClassConstantInitializers(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+class C {
+	const A = 1 + 1;
+	const B = self::A * FACTOR;
+	public static $s = self::B;
+}`)
+
+	decl := findClassDecl(t, bytecode, "C")
+	if value := decl.Class.Constants["A"].Value; value.ToInt() != 2 {
+		t.Errorf("Expected A folded to 2, got %v", value)
+	}
+
+	// B is initialized after DECLARE_CLASS, before the static property,
+	// with self resolved to the class
+	var order []vm.Opcode
+	for _, instr := range bytecode.Instructions {
+		switch instr.Opcode {
+		case vm.OpDeclareClass, vm.OpDeclareConst, vm.OpAssignStaticProp:
+			order = append(order, instr.Opcode)
+		case vm.OpFetchClassConstant:
+			if class := bytecode.Constants[instr.Op1.Value]; class != "C" {
+				t.Errorf("Expected self:: resolved to C, got %v", class)
+			}
+		}
+	}
+	if len(order) != 3 || order[0] != vm.OpDeclareClass || order[1] != vm.OpDeclareConst || order[2] != vm.OpAssignStaticProp {
+		t.Errorf("Expected DECLARE_CLASS, DECLARE_CONST, ASSIGN_STATIC_PROP, got %v", order)
+	}
+}
@@ -0,0 +1,198 @@
+package compiler
+
+import (
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// Data-Flow Passes
+// ========================================
+
+// maxDataFlowRounds bounds the rounds of data-flow passes; removing a
+// store can make the stores feeding it dead too
+const maxDataFlowRounds = 8
+
+// optimizeDataFlow rewrites an op array with the passes working on its SSA
+// form. Like the peephole rules they replace removed instructions with
+// NOPs, compacted away last. fn is nil for the main op array.
+func optimizeDataFlow(instructions vm.Instructions, constants []interface{}, fn *vm.CompiledFunction) vm.Instructions {
+	for round := 0; round < maxDataFlowRounds; round++ {
+		s := buildSSA(instructions, constants, fn)
+		if s == nil {
+			break
+		}
+		changed := s.removeRedundantConversions()
+		changed = s.specializeOperations() || changed
+		changed = s.removeDeadStores() || changed
+		if !changed {
+			break
+		}
+	}
+	// The form is not built for op arrays with exception regions, so
+	// there is no exception table to relocate
+	return compactNops(instructions, constants, nil)
+}
+
+// reachable reports whether an instruction is in a reachable block; the
+// form has no values for the others
+func (s *ssaForm) reachable(pos int) bool {
+	return s.blocks[s.blockOf[pos]].reachable
+}
+
+// removeRedundantConversions turns a CAST to a type its operand always has,
+// or a BOOL of a bool, into a plain copy
+func (s *ssaForm) removeRedundantConversions() bool {
+	changed := false
+	for pos := range s.instructions {
+		instr := &s.instructions[pos]
+		if !s.reachable(pos) {
+			continue
+		}
+		switch instr.Opcode {
+		case vm.OpCast:
+			target, ok := castTypes[instr.ExtendedValue]
+			if !ok || !s.op1Type(pos).within(target) {
+				continue
+			}
+		case vm.OpBool:
+			if !s.op1Type(pos).within(typeBool) {
+				continue
+			}
+		default:
+			continue
+		}
+		*instr = vm.Instruction{Opcode: vm.OpQMAssign, Op1: instr.Op1, Result: instr.Result, Lineno: instr.Lineno}
+		changed = true
+	}
+	return changed
+}
+
+// specializeOperations marks ADD, SUB and MUL on operands of known numeric
+// types with the arithmetic the VM can use directly, and turns a CONCAT of
+// two strings into a FAST_CONCAT
+func (s *ssaForm) specializeOperations() bool {
+	changed := false
+	for pos := range s.instructions {
+		instr := &s.instructions[pos]
+		if !s.reachable(pos) {
+			continue
+		}
+		left, right := s.op1Type(pos), s.op2Type(pos)
+		switch instr.Opcode {
+		case vm.OpAdd, vm.OpSub, vm.OpMul:
+			var hint uint32
+			switch {
+			case left.within(typeLong) && right.within(typeLong):
+				hint = vm.ArithLong
+			case left.within(typeNumber) && right.within(typeNumber) && (left == typeDouble || right == typeDouble):
+				hint = vm.ArithDouble
+			}
+			if hint != 0 && instr.ExtendedValue != hint {
+				instr.ExtendedValue = hint
+				changed = true
+			}
+		case vm.OpConcat:
+			if left.within(typeString) && right.within(typeString) {
+				instr.Opcode = vm.OpFastConcat
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// removeDeadStores removes the instructions without side effects whose
+// value is never read. Only stores to slots that always hold scalars are
+// removed, as dropping an object or array earlier or later would move the
+// call of its destructors.
+func (s *ssaForm) removeDeadStores() bool {
+	live := s.liveValues()
+	scalar := make(map[ssaVariable]bool)
+	for variable := range s.tracked {
+		scalar[variable] = true
+	}
+	for _, value := range s.values {
+		if !value.typ.within(typeScalar | typeUndef) {
+			scalar[value.variable] = false
+		}
+	}
+
+	changed := false
+	for pos := range s.instructions {
+		value := s.result[pos]
+		if value == nil || live[value] || !scalar[value.variable] || !s.isPure(pos) {
+			continue
+		}
+		s.instructions[pos] = nop(s.instructions[pos].Lineno)
+		changed = true
+	}
+	return changed
+}
+
+// liveValues returns the values some instruction may read, directly or
+// through phis. A FREE does not read the value it clears, but the value
+// it leaves in a slot shared with a compiled variable is its prior one.
+func (s *ssaForm) liveValues() map[*ssaValue]bool {
+	live := make(map[*ssaValue]bool)
+	var work []*ssaValue
+	mark := func(value *ssaValue) {
+		if value != nil && !live[value] {
+			live[value] = true
+			work = append(work, value)
+		}
+	}
+	for pos, instr := range s.instructions {
+		if instr.Opcode == vm.OpFree {
+			continue
+		}
+		mark(s.op1[pos])
+		mark(s.op2[pos])
+		mark(s.prior[pos])
+	}
+	for len(work) > 0 {
+		value := work[len(work)-1]
+		work = work[:len(work)-1]
+		if value.phi != nil {
+			for _, arg := range value.phi.args {
+				mark(arg)
+			}
+		} else if value.def >= 0 && s.instructions[value.def].Opcode == vm.OpFree {
+			mark(s.prior[value.def])
+		}
+	}
+	return live
+}
+
+// isPure reports whether an instruction only computes its result: it has
+// no side effect, warns about nothing and throws nothing for the types of
+// its operands
+func (s *ssaForm) isPure(pos int) bool {
+	instr := s.instructions[pos]
+	left, right := s.op1Type(pos), s.op2Type(pos)
+	integral := typeUndef | typeNull | typeBool | typeLong
+	scalar := typeUndef | typeScalar
+	switch instr.Opcode {
+	case vm.OpQMAssign, vm.OpFree, vm.OpIsIdentical, vm.OpIsNotIdentical:
+		return true
+	case vm.OpAssign:
+		return instr.ExtendedValue&vm.FetchByName == 0
+	case vm.OpFetchR:
+		// Reading an undefined variable warns
+		return instr.Op1.IsCV() && instr.ExtendedValue&vm.FetchByName == 0 && left&typeUndef == 0
+	case vm.OpAdd, vm.OpSub, vm.OpMul:
+		return left.within(integral|typeDouble) && right.within(integral|typeDouble)
+	case vm.OpBWAnd, vm.OpBWOr, vm.OpBWXor:
+		return left.within(integral) && right.within(integral)
+	case vm.OpConcat, vm.OpFastConcat, vm.OpIsEqual, vm.OpIsNotEqual, vm.OpIsSmaller,
+		vm.OpIsSmallerOrEqual, vm.OpSpaceship:
+		return left.within(scalar) && right.within(scalar)
+	case vm.OpBool, vm.OpBoolNot:
+		return left.within(scalar | typeArray)
+	case vm.OpCast:
+		switch instr.ExtendedValue {
+		case 1, 2, 3, 4, 7:
+			return left.within(scalar)
+		}
+	}
+	return false
+}
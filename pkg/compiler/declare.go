@@ -0,0 +1,78 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// Declare Statements
+// ========================================
+
+// compileDeclare compiles declare(...). strict_types=1 makes the calls,
+// returns and property writes compiled in the file use strict typing;
+// ticks=N emits TICKS after each statement in its scope, the block or,
+// without one, the rest of the file.
+func (c *Compiler) compileDeclare(node *ast.DeclareStatement) error {
+	ticks := c.ticks
+	for _, directive := range node.Directives {
+		switch directive.Name {
+		case "strict_types":
+			if node.Body != nil {
+				return fmt.Errorf("strict_types declaration must not use block mode")
+			}
+			if c.firstStatement != ast.Stmt(node) {
+				return fmt.Errorf("strict_types declaration must be the very first statement in the script")
+			}
+			value, ok := directive.Value.(*ast.IntegerLiteral)
+			if !ok || (value.Value != 0 && value.Value != 1) {
+				return fmt.Errorf("strict_types declaration must have 0 or 1 as its value")
+			}
+			c.strictTypes = value.Value == 1
+		case "ticks":
+			value, ok := directive.Value.(*ast.IntegerLiteral)
+			if !ok {
+				return fmt.Errorf("declare(ticks) value must be a literal")
+			}
+			c.ticks = int(value.Value)
+		}
+		// encoding and unknown directives have no effect (PHP only
+		// warns about the latter)
+	}
+
+	if node.Body == nil {
+		return nil
+	}
+	err := c.Compile(node.Body)
+	c.ticks = ticks
+	return err
+}
+
+// compileStatement compiles a statement of a statement list, followed
+// under declare(ticks=N) by a TICKS instruction. Declarations and blocks,
+// whose own statements tick, are not followed by one.
+func (c *Compiler) compileStatement(stmt ast.Stmt) error {
+	if err := c.Compile(stmt); err != nil {
+		return err
+	}
+	if c.ticks <= 0 {
+		return nil
+	}
+	switch stmt.(type) {
+	case *ast.BlockStatement, *ast.DeclareStatement, *ast.FunctionDeclaration, *ast.ClassDeclaration,
+		*ast.InterfaceDeclaration, *ast.TraitDeclaration, *ast.EnumDeclaration:
+		return nil
+	}
+
+	var line uint32
+	if len(c.instructions) > 0 {
+		line = c.instructions[c.lastInstruction.Position].Lineno
+	}
+	c.EmitWithExtended(vm.OpTicks, line, uint32(c.ticks),
+		vm.UnusedOperand(),
+		vm.UnusedOperand(),
+		vm.UnusedOperand())
+	return nil
+}
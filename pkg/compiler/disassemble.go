@@ -0,0 +1,328 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// Disassembler
+// ========================================
+
+// maxStringConstant is the longest string constant shown in full in a
+// listing
+const maxStringConstant = 40
+
+// opArray is one op array of a listing: the main script, or the body of a
+// function, method or closure
+type opArray struct {
+	name         string
+	instructions vm.Instructions
+	constants    []interface{}
+	numParams    int
+	variables    []string                // Compiled variable names
+	tryCatch     []types.TryCatchElement // Exception table
+}
+
+// Disassemble returns a listing of compiled bytecode in the style of
+// OPcache's opcode dumps: the main op array, then each op array of the
+// function table. Each instruction shows its number, source line, result,
+// opcode name and operands; constants are resolved to their values,
+// compiled variables to their names and jump targets to labels. The
+// exception table follows the instructions, a region per line: its try,
+// catch, finally and finally end instructions.
+func Disassemble(bytecode *Bytecode) string {
+	var sb strings.Builder
+	disassembleOpArray(&sb, opArray{
+		name:         "$_main",
+		instructions: bytecode.Instructions,
+		constants:    bytecode.Constants,
+		variables:    bytecode.Variables,
+		tryCatch:     bytecode.TryCatch,
+	})
+	for _, fn := range bytecode.Functions {
+		sb.WriteString("\n")
+		disassembleOpArray(&sb, opArray{
+			name:         fn.Name,
+			instructions: fn.Instructions,
+			constants:    fn.Constants,
+			numParams:    fn.NumParams,
+			variables:    fn.Variables,
+			tryCatch:     fn.TryCatch,
+		})
+	}
+	return sb.String()
+}
+
+// jumpLabels numbers the jump targets of an op array in instruction order
+func jumpLabels(array opArray) map[int]string {
+	var targets []int
+	seen := make(map[int]bool)
+	add := func(target int) {
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	for _, instr := range array.instructions {
+		if target, ok := jumpTarget(instr); ok {
+			add(target)
+		}
+		if table := jumpTableOperand(array.constants, instr); table != nil {
+			for _, target := range table.Longs {
+				add(target)
+			}
+			for _, target := range table.Strings {
+				add(target)
+			}
+			add(table.Default)
+		}
+	}
+	sort.Ints(targets)
+	labels := make(map[int]string, len(targets))
+	for i, target := range targets {
+		labels[target] = fmt.Sprintf(".L%d", i+1)
+	}
+	return labels
+}
+
+// jumpTarget returns the instruction a jump opcode may continue at
+func jumpTarget(instr vm.Instruction) (int, bool) {
+	target := jumpOperand(&instr)
+	if target == nil {
+		return 0, false
+	}
+	return int(target.Value), true
+}
+
+// jumpOperand returns the operand holding the target of a jump opcode: Op1
+// for unconditional jumps and Op2 for the others
+func jumpOperand(instr *vm.Instruction) *vm.Operand {
+	var target *vm.Operand
+	switch instr.Opcode {
+	case vm.OpJmp, vm.OpFastCall:
+		target = &instr.Op1
+	case vm.OpJmpZ, vm.OpJmpNZ, vm.OpJmpZEx, vm.OpJmpNZEx, vm.OpJmpSet, vm.OpCoalesce, vm.OpJmpNull,
+		vm.OpCatch, vm.OpBindInitStaticOrJmp, vm.OpFeFetchR, vm.OpFeFetchRW, vm.OpNew:
+		target = &instr.Op2
+	default:
+		return nil
+	}
+	if !target.IsConst() {
+		return nil
+	}
+	return target
+}
+
+// jumpTableOperand returns the jump table of a SWITCH_LONG, SWITCH_STRING
+// or MATCH instruction
+func jumpTableOperand(constants []interface{}, instr vm.Instruction) *vm.JumpTable {
+	switch instr.Opcode {
+	case vm.OpSwitchLong, vm.OpSwitchString, vm.OpMatch:
+		if instr.Op2.IsConst() && int(instr.Op2.Value) < len(constants) {
+			table, _ := constants[instr.Op2.Value].(*vm.JumpTable)
+			return table
+		}
+	}
+	return nil
+}
+
+// immediateOperands reports which CONST operands of an opcode hold a
+// number rather than a constant table index
+func immediateOperands(opcode vm.Opcode) (op1, op2 bool) {
+	switch opcode {
+	case vm.OpRecv, vm.OpRecvInit, vm.OpRecvVariadic:
+		return true, false
+	case vm.OpInitFcallByName, vm.OpInitDynamicCall:
+		return false, true
+	}
+	return false, false
+}
+
+// disassembleOpArray writes the listing of one op array
+func disassembleOpArray(sb *strings.Builder, array opArray) {
+	tmps := 0
+	minLine, maxLine := uint32(0), uint32(0)
+	for _, instr := range array.instructions {
+		for _, op := range []vm.Operand{instr.Op1, instr.Op2, instr.Result} {
+			if op.IsTmpVar() && int(op.Value)+1 > tmps {
+				tmps = int(op.Value) + 1
+			}
+		}
+		if instr.Lineno != 0 {
+			if minLine == 0 || instr.Lineno < minLine {
+				minLine = instr.Lineno
+			}
+			maxLine = max(maxLine, instr.Lineno)
+		}
+	}
+
+	fmt.Fprintf(sb, "%s:\n", array.name)
+	fmt.Fprintf(sb, "     ; (lines=%d, args=%d, vars=%d, tmps=%d)\n",
+		len(array.instructions), array.numParams, len(array.variables), tmps)
+	if minLine != 0 {
+		fmt.Fprintf(sb, "     ; source lines %d-%d\n", minLine, maxLine)
+	}
+	if len(array.variables) > 0 {
+		vars := make([]string, len(array.variables))
+		for i, name := range array.variables {
+			vars[i] = fmt.Sprintf("CV%d($%s)", i, name)
+		}
+		fmt.Fprintf(sb, "     ; vars: %s\n", strings.Join(vars, ", "))
+	}
+
+	d := &disassembler{array: array, labels: jumpLabels(array)}
+	for pos, instr := range array.instructions {
+		if label, ok := d.labels[pos]; ok {
+			fmt.Fprintf(sb, "%s:\n", label)
+		}
+		fmt.Fprintf(sb, "%04d L%-4d %s\n", pos, instr.Lineno, d.instruction(instr))
+	}
+	// Jumps past the last instruction end the op array
+	if label, ok := d.labels[len(array.instructions)]; ok {
+		fmt.Fprintf(sb, "%s:\n", label)
+	}
+
+	if len(array.tryCatch) > 0 {
+		sb.WriteString("EXCEPTION TABLE:\n")
+		for _, tc := range array.tryCatch {
+			op := func(pos int) string {
+				if pos == 0 {
+					return "-"
+				}
+				return fmt.Sprintf("%04d", pos)
+			}
+			fmt.Fprintf(sb, "     %04d, %s, %s, %s\n", tc.TryOp, op(tc.CatchOp), op(tc.FinallyOp), op(tc.FinallyEnd))
+		}
+	}
+}
+
+// disassembler formats the instructions of one op array
+type disassembler struct {
+	array  opArray
+	labels map[int]string
+}
+
+// instruction formats an instruction as "result = OPCODE op1, op2"
+func (d *disassembler) instruction(instr vm.Instruction) string {
+	immediate1, immediate2 := immediateOperands(instr.Opcode)
+	target, isJump := jumpTarget(instr)
+
+	var operands []string
+	for i, op := range []vm.Operand{instr.Op1, instr.Op2} {
+		if op.IsUnused() {
+			continue
+		}
+		switch {
+		case isJump && op.IsConst() && int(op.Value) == target:
+			operands = append(operands, d.label(target))
+		case i == 0 && immediate1, i == 1 && immediate2:
+			operands = append(operands, strconv.FormatUint(uint64(op.Value), 10))
+		default:
+			operands = append(operands, d.operand(op))
+		}
+	}
+	extended := instr.ExtendedValue
+	if instr.Opcode == vm.OpDoFcall && extended&vm.CallTail != 0 {
+		extended &^= vm.CallTail
+		operands = append(operands, "(tail)")
+	}
+	if extended != 0 {
+		operands = append(operands, fmt.Sprintf("(ext=%d)", extended))
+	}
+
+	text := instr.Opcode.String()
+	if len(operands) > 0 {
+		text += " " + strings.Join(operands, ", ")
+	}
+	if !instr.Result.IsUnused() {
+		text = d.operand(instr.Result) + " = " + text
+	}
+	return text
+}
+
+// operand formats an operand: CV0($name), T1, V2 or a constant value
+func (d *disassembler) operand(op vm.Operand) string {
+	switch op.Type {
+	case vm.OpCV:
+		if int(op.Value) < len(d.array.variables) {
+			return fmt.Sprintf("CV%d($%s)", op.Value, d.array.variables[op.Value])
+		}
+		return fmt.Sprintf("CV%d", op.Value)
+	case vm.OpTmpVar:
+		return fmt.Sprintf("T%d", op.Value)
+	case vm.OpVar:
+		return fmt.Sprintf("V%d", op.Value)
+	case vm.OpConst:
+		if int(op.Value) >= len(d.array.constants) {
+			return fmt.Sprintf("CONST(%d)", op.Value)
+		}
+		return d.constant(d.array.constants[op.Value])
+	}
+	return op.String()
+}
+
+// constant formats a constant table entry
+func (d *disassembler) constant(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return fmt.Sprintf("bool(%t)", v)
+	case int64:
+		return fmt.Sprintf("int(%d)", v)
+	case float64:
+		return fmt.Sprintf("float(%s)", strconv.FormatFloat(v, 'G', -1, 64))
+	case string:
+		if len(v) > maxStringConstant {
+			return fmt.Sprintf("string(%s...)", strconv.Quote(v[:maxStringConstant]))
+		}
+		return fmt.Sprintf("string(%s)", strconv.Quote(v))
+	case *vm.FunctionDecl:
+		if v.Function.Name == "{closure}" {
+			return "{closure}"
+		}
+		return fmt.Sprintf("function %s()", v.Function.Name)
+	case *vm.ClassDecl:
+		return fmt.Sprintf("class %s", v.Class.Name)
+	case *vm.JumpTable:
+		return d.jumpTable(v)
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// jumpTable formats a jump table as its cases and default, with labels
+func (d *disassembler) jumpTable(table *vm.JumpTable) string {
+	var cases []string
+	longs := make([]int64, 0, len(table.Longs))
+	for key := range table.Longs {
+		longs = append(longs, key)
+	}
+	sort.Slice(longs, func(i, j int) bool { return longs[i] < longs[j] })
+	for _, key := range longs {
+		cases = append(cases, fmt.Sprintf("%d: %s", key, d.label(table.Longs[key])))
+	}
+	strs := make([]string, 0, len(table.Strings))
+	for key := range table.Strings {
+		strs = append(strs, key)
+	}
+	sort.Strings(strs)
+	for _, key := range strs {
+		cases = append(cases, fmt.Sprintf("%s: %s", strconv.Quote(key), d.label(table.Strings[key])))
+	}
+	cases = append(cases, "default: "+d.label(table.Default))
+	return "[" + strings.Join(cases, ", ") + "]"
+}
+
+// label returns the label of a jump target
+func (d *disassembler) label(target int) string {
+	if label, ok := d.labels[target]; ok {
+		return label
+	}
+	return fmt.Sprintf("%04d", target)
+}
@@ -0,0 +1,86 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+func TestDisassemble_OpArrays(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+function add($a, $b = 2) { return $a + $b; }
+class Greeter { public function greet($name) { return "Hi " . $name; } }
+$f = function ($x) { return $x; };
+echo add(1);`)
+
+	listing := Disassemble(bytecode)
+	for _, want := range []string{
+		"$_main:\n",
+		"add:\n     ; (lines=",
+		"args=2, vars=2",
+		"; vars: CV0($a), CV1($b)",
+		"Greeter::greet:\n",
+		"; vars: CV0($name)",
+		"{closure}:\n",
+		"T0 = DECLARE_LAMBDA_FUNCTION {closure}",
+		"CV0($a) = RECV 0",
+		"CV1($b) = RECV_INIT 1, T0",
+		"DECLARE_FUNCTION function add()",
+		"DECLARE_CLASS class Greeter",
+		`string("Hi ")`,
+		`INIT_FCALL_BY_NAME string("add"), 1`,
+	} {
+		if !strings.Contains(listing, want) {
+			t.Errorf("Listing does not contain %q:\n%s", want, listing)
+		}
+	}
+
+	// Bodies are listed once, in their own op array
+	if n := strings.Count(listing, "RECV_INIT"); n != 1 {
+		t.Errorf("Expected RECV_INIT listed once, got %d:\n%s", n, listing)
+	}
+	main := listing[:strings.Index(listing, "\nadd:")]
+	if strings.Contains(main, "RETURN") {
+		t.Errorf("Main op array lists a function body:\n%s", main)
+	}
+}
+
+func TestDisassemble_JumpLabels(t *testing.T) {
+	bytecode := &Bytecode{
+		Instructions: vm.Instructions{
+			{Opcode: vm.OpJmpZ, Op1: vm.CVOperand(0), Op2: vm.ConstOperand(3), Lineno: 1},
+			{Opcode: vm.OpEcho, Op1: vm.ConstOperand(0), Lineno: 2},
+			{Opcode: vm.OpJmp, Op1: vm.ConstOperand(4), Lineno: 2},
+			{Opcode: vm.OpEcho, Op1: vm.ConstOperand(1), Lineno: 3},
+		},
+		Constants: []interface{}{"yes", 1.5},
+		Variables: []string{"ok"},
+	}
+
+	want := `$_main:
+     ; (lines=4, args=0, vars=1, tmps=0)
+     ; source lines 1-3
+     ; vars: CV0($ok)
+0000 L1    JMPZ CV0($ok), .L1
+0001 L2    ECHO string("yes")
+0002 L2    JMP .L2
+.L1:
+0003 L3    ECHO float(1.5)
+.L2:
+`
+	if got := Disassemble(bytecode); got != want {
+		t.Errorf("Unexpected listing:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDisassemble_JumpTable(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+switch ($x) { case 'a': echo 1; break; case 'b': echo 2; break; default: echo 3; }`)
+
+	listing := Disassemble(bytecode)
+	if !strings.Contains(listing, `SWITCH_STRING T1, ["a": .L`) || !strings.Contains(listing, `"b": .L`) ||
+		!strings.Contains(listing, "default: .L") {
+		t.Errorf("Jump table not resolved to labels:\n%s", listing)
+	}
+}
@@ -0,0 +1,109 @@
+package compiler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// Compiled programs exercising the dispatch loop, run at each optimization
+// level. Each benchmark reports the instructions executed per second
+// (instr/s) next to the time per run, the figure to compare across changes
+// to the loop.
+
+// runBenchSource compiles a script at every optimization level and
+// executes it b.N times on one VM, checking the output of the last run.
+// The functions the script calls are declared once beforehand, as a
+// function cannot be declared again.
+func runBenchSource(b *testing.B, declarations, source, want string) {
+	for _, level := range []OptimizationLevel{OptimizeNone, OptimizePeephole, OptimizeDataFlow} {
+		b.Run(fmt.Sprintf("O%d", level), func(b *testing.B) {
+			compile := ScriptCompiler(level)
+			script, err := compile("bench.php", []byte(source))
+			if err != nil {
+				b.Fatalf("Compilation failed: %v", err)
+			}
+
+			machine := vm.New()
+			if declarations != "" {
+				functions, err := compile("functions.php", []byte(declarations))
+				if err != nil {
+					b.Fatalf("Compilation failed: %v", err)
+				}
+				if err := machine.ExecuteScript(functions); err != nil {
+					b.Fatalf("Declaring the functions failed: %v", err)
+				}
+			}
+			start := machine.InstructionCount()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				machine.ClearOutput()
+				if err := machine.ExecuteScript(script); err != nil {
+					b.Fatalf("Execution failed: %v", err)
+				}
+			}
+			b.StopTimer()
+
+			if got := machine.GetOutput(); got != want {
+				b.Fatalf("Expected output %q, got %q", want, got)
+			}
+			b.ReportMetric(float64(machine.InstructionCount()-start)/b.Elapsed().Seconds(), "instr/s")
+		})
+	}
+}
+
+// BenchmarkDispatch_Fib benchmarks the recursive fib(20): calls, compares
+// and integer arithmetic
+func BenchmarkDispatch_Fib(b *testing.B) {
+	runBenchSource(b, `<?php
+function fib($n) { if ($n < 2) { return $n; } return fib($n - 1) + fib($n - 2); }`,
+		`<?php echo fib(20);`, "6765")
+}
+
+// BenchmarkDispatch_Mandelbrot benchmarks counting the points of a 16x16
+// grid in the Mandelbrot set: nested loops of float arithmetic
+func BenchmarkDispatch_Mandelbrot(b *testing.B) {
+	runBenchSource(b, "", `<?php
+$count = 0;
+for ($y = 0; $y < 16; $y++) {
+    $ci = $y * 0.15625 - 1.25;
+    for ($x = 0; $x < 16; $x++) {
+        $cr = $x * 0.15625 - 2.0;
+        $zr = 0.0;
+        $zi = 0.0;
+        $inside = true;
+        for ($i = 0; $i < 32; $i++) {
+            $r2 = $zr * $zr;
+            $i2 = $zi * $zi;
+            if ($r2 + $i2 > 4) {
+                $inside = false;
+                break;
+            }
+            $zi = $zr * $zi * 2 + $ci;
+            $zr = $r2 - $i2 + $cr;
+        }
+        if ($inside) {
+            $count++;
+        }
+    }
+}
+echo $count;`, "67")
+}
+
+// BenchmarkDispatch_ArraySum benchmarks filling an array with 1000
+// integers and summing them: array writes, reads and integer loops
+func BenchmarkDispatch_ArraySum(b *testing.B) {
+	runBenchSource(b, "", `<?php
+$a = [];
+for ($i = 0; $i < 1000; $i++) {
+    $a[$i] = $i;
+}
+$sum = 0;
+for ($i = 0; $i < 1000; $i++) {
+    $sum += $a[$i];
+}
+echo $sum;`, "499500")
+}
@@ -0,0 +1,61 @@
+package compiler
+
+import (
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// Variable Variables and Static Properties
+// ========================================
+
+// compileVariableVariable compiles a read of $$name or ${expr} into temp 0:
+// the name is evaluated, then the variable is fetched by name
+func (c *Compiler) compileVariableVariable(node *ast.VariableVariable) error {
+	if err := c.Compile(node.Name); err != nil {
+		return err
+	}
+	c.EmitWithExtended(vm.OpFetchR, uint32(node.Token.Pos.Line), vm.FetchByName,
+		vm.TmpVarOperand(0),
+		vm.UnusedOperand(),
+		vm.TmpVarOperand(0))
+	return nil
+}
+
+// compileVariableVariableAssignment assigns the value in temp 0 to $$name,
+// leaving the assigned value in temp 0
+func (c *Compiler) compileVariableVariableAssignment(target *ast.VariableVariable, line uint32) error {
+	value := c.keepValue(line)
+	if err := c.Compile(target.Name); err != nil {
+		return err
+	}
+	c.EmitWithExtended(vm.OpAssign, line, vm.FetchByName,
+		vm.TmpVarOperand(0),
+		value,
+		vm.TmpVarOperand(0))
+	return nil
+}
+
+// compileStaticProperty compiles a read of Class::$prop, $class::$prop or
+// Class::$$name into temp 0
+func (c *Compiler) compileStaticProperty(node *ast.StaticPropertyExpression) error {
+	class, name, err := c.compileStaticMember(node)
+	if err != nil {
+		return err
+	}
+	c.EmitWithLine(vm.OpFetchStaticPropR, uint32(node.Token.Pos.Line), class, name, vm.TmpVarOperand(0))
+	return nil
+}
+
+// compileStaticPropertyAssignment assigns the value in temp 0 to a static
+// property, leaving it in temp 0
+func (c *Compiler) compileStaticPropertyAssignment(target *ast.StaticPropertyExpression, line uint32) error {
+	value := c.keepValue(line)
+	class, name, err := c.compileStaticMember(target)
+	if err != nil {
+		return err
+	}
+	c.EmitWithLine(vm.OpAssignStaticProp, line, class, name, value)
+	c.EmitWithLine(vm.OpQMAssign, line, value, vm.UnusedOperand(), vm.TmpVarOperand(0))
+	return nil
+}
@@ -0,0 +1,78 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+func TestCompileVariableVariables(t *testing.T) {
+	bytecode, err := compileSource(`<?php
+$name = 'x';
+$$name = 1;
+echo ${'x'};
+echo isset($$name) ? 1 : 0, $$name ?? 2;
+unset($$name);`)
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+
+	byName := map[vm.Opcode]int{}
+	for _, instr := range bytecode.Instructions {
+		if instr.ExtendedValue&vm.FetchByName != 0 {
+			byName[instr.Opcode]++
+		}
+		if instr.Opcode == vm.OpIssetIsemptyVar {
+			byName[instr.Opcode]++
+		}
+	}
+	for _, op := range []vm.Opcode{vm.OpAssign, vm.OpFetchR, vm.OpIssetIsemptyVar, vm.OpFetchIs, vm.OpUnsetVar} {
+		if byName[op] != 1 {
+			t.Errorf("Expected one %s by name, got %d", op, byName[op])
+		}
+	}
+}
+
+func TestCompileDynamicAccess(t *testing.T) {
+	bytecode, err := compileSource(`<?php
+class Counter { public static $count = 0; }
+$class = 'Counter';
+$prop = 'count';
+Counter::$count = 1;
+echo $class::$$prop;
+$f = 'Counter::bump';
+$f();
+$obj = new $class;
+$m = 'run';
+$obj->$m();`)
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+
+	var assign, fetch, dynamicCall, methodCall *vm.Instruction
+	for i, instr := range bytecode.Instructions {
+		switch instr.Opcode {
+		case vm.OpAssignStaticProp:
+			assign = &bytecode.Instructions[i]
+		case vm.OpFetchStaticPropR:
+			fetch = &bytecode.Instructions[i]
+		case vm.OpInitDynamicCall:
+			dynamicCall = &bytecode.Instructions[i]
+		case vm.OpInitMethodCall:
+			methodCall = &bytecode.Instructions[i]
+		}
+	}
+
+	if assign == nil || assign.Op1.Type != vm.OpConst || bytecode.Constants[assign.Op1.Value] != "Counter" {
+		t.Errorf("Expected ASSIGN_STATIC_PROP on the named class, got %v", assign)
+	}
+	if fetch == nil || fetch.Op1.Type == vm.OpConst || fetch.Op2.Type == vm.OpConst {
+		t.Errorf("Expected FETCH_STATIC_PROP_R with a computed class and name, got %v", fetch)
+	}
+	if dynamicCall == nil {
+		t.Error("Expected $f() to emit INIT_DYNAMIC_CALL")
+	}
+	if methodCall == nil || methodCall.Op2.Type == vm.OpConst {
+		t.Errorf("Expected INIT_METHOD_CALL with a computed method name, got %v", methodCall)
+	}
+}
@@ -0,0 +1,159 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ========================================
+// Enums
+// ========================================
+
+// enumMagicMethods are the magic methods enums may not declare
+var enumMagicMethods = []string{
+	"__construct", "__destruct", "__clone", "__get", "__set", "__unset", "__isset",
+	"__toString", "__debugInfo", "__serialize", "__unserialize", "__sleep", "__wakeup", "__set_state",
+}
+
+// compileEnum checks an enum declaration and compiles it as a final class
+// with its cases. Case values must be constant
+// expressions of the backing type, distinct across cases.
+func (c *Compiler) compileEnum(node *ast.EnumDeclaration) error {
+	name := node.Name.Value
+
+	backingType := ""
+	if node.BackingType != nil {
+		backingType = strings.ToLower(node.BackingType.Value)
+		if backingType != "int" && backingType != "string" {
+			return fmt.Errorf("enum backing type must be int or string, %s given", node.BackingType.Value)
+		}
+	}
+
+	decl, err := c.newClassDecl(name, node.Attributes, node.DocComment)
+	if err != nil {
+		return err
+	}
+	class := decl.Class
+	decl.Class = types.NewEnumEntry(class.Name, backingType)
+	decl.Class.Attributes = class.Attributes
+	decl.Class.DocComment = class.DocComment
+	decl.Class.IsFinal = true
+	outer := c.enterClass(decl.Class.Name, false)
+	defer func() { c.scope = outer }()
+	for _, iface := range node.Implements {
+		decl.Interfaces = append(decl.Interfaces, c.namespace.ResolveClassName(iface.Value))
+	}
+
+	cases := make(map[string]bool)
+	values := make(map[interface{}]string)
+	var members []ast.Stmt
+	for _, stmt := range node.Body {
+		switch member := stmt.(type) {
+		case *ast.EnumCaseDeclaration:
+			caseName := member.Name.Value
+			if cases[caseName] {
+				return fmt.Errorf("cannot redefine class constant %s::%s", name, caseName)
+			}
+			cases[caseName] = true
+
+			if member.Value == nil {
+				if backingType != "" {
+					return fmt.Errorf("case %s of backed enum %s must have a value", caseName, name)
+				}
+				decl.Class.AddCase(caseName, nil)
+				continue
+			}
+			if backingType == "" {
+				return fmt.Errorf("case %s of non-backed enum %s must not have a value", caseName, name)
+			}
+
+			value, ok := constantExpressionValue(member.Value)
+			if !ok {
+				return fmt.Errorf("enum case value must be compile-time evaluatable")
+			}
+			switch value.(type) {
+			case int64:
+				ok = backingType == "int"
+			case string:
+				ok = backingType == "string"
+			default:
+				ok = false
+			}
+			if !ok {
+				return fmt.Errorf("enum case type %s does not match enum backing type %s", phpTypeName(value), backingType)
+			}
+			if other, exists := values[value]; exists {
+				return fmt.Errorf("duplicate value in enum %s for cases %s and %s", name, other, caseName)
+			}
+			values[value] = caseName
+			decl.Class.AddCase(caseName, constantToValue(value))
+
+		case *ast.PropertyDeclaration:
+			return fmt.Errorf("enum %s cannot include properties", name)
+
+		case *ast.MethodDeclaration:
+			for _, magic := range enumMagicMethods {
+				if strings.EqualFold(member.Name.Value, magic) {
+					return fmt.Errorf("enum %s cannot include magic method %s", name, magic)
+				}
+			}
+			members = append(members, member)
+
+		default:
+			members = append(members, member)
+		}
+	}
+
+	init, err := c.compileClassBody(decl, members)
+	if err != nil {
+		return err
+	}
+	c.emitDeclareClass(decl, uint32(node.Token.Pos.Line))
+	return c.compileInitializers(decl, init)
+}
+
+// constantExpressionValue evaluates a constant expression made of
+// literals and operators, as constant folding does
+func constantExpressionValue(expr ast.Expr) (interface{}, bool) {
+	switch node := expr.(type) {
+	case *ast.GroupedExpression:
+		return constantExpressionValue(node.Expr)
+	case *ast.PrefixExpression:
+		operand, ok := constantExpressionValue(node.Right)
+		if !ok {
+			return nil, false
+		}
+		return foldConstantUnaryOp(operand, node.Operator)
+	case *ast.InfixExpression:
+		left, ok := constantExpressionValue(node.Left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := constantExpressionValue(node.Right)
+		if !ok {
+			return nil, false
+		}
+		return foldConstantBinaryOp(left, right, node.Operator)
+	}
+	return getConstantValue(expr)
+}
+
+// phpTypeName returns the PHP type name of a constant value
+func phpTypeName(value interface{}) string {
+	switch value.(type) {
+	case int64:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,149 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// Exception Regions
+// ========================================
+
+// tryContext tracks a try statement of the op array being emitted, for
+// the jumps and returns leaving it
+type tryContext struct {
+	// region is the index of the statement's exception table entry
+	region int
+
+	// finally is set if the statement has a finally block
+	finally bool
+
+	// inFinally is set while its finally block is being compiled
+	inFinally bool
+
+	// fastCalls holds the positions of the FAST_CALLs entering the
+	// finally block, patched once its position is known
+	fastCalls []int
+}
+
+// compileTry compiles a try statement into a region of the exception
+// table, which the VM consults when unwinding:
+//
+//	try:     try block, FAST_CALL finally, JMP end
+//	catch:   CATCH A (next: the following CATCH), catch block,
+//	         FAST_CALL finally, JMP end, ... CATCH Z (last)
+//	finally: finally block, FAST_RET
+//	end:
+//
+// The FAST_CALLs are only emitted with a finally block. Exceptions thrown
+// in the try block continue at the first CATCH, those the catch chain does
+// not handle or thrown in a catch block run the finally block and are
+// rethrown by its FAST_RET.
+func (c *Compiler) compileTry(node *ast.TryStatement) error {
+	line := uint32(node.Token.Pos.Line)
+	try := &tryContext{region: len(c.tryCatch), finally: node.Finally != nil}
+	c.tryCatch = append(c.tryCatch, types.TryCatchElement{TryOp: c.CurrentPosition()})
+	c.tryStack = append(c.tryStack, try)
+	defer func() { c.tryStack = c.tryStack[:len(c.tryStack)-1] }()
+
+	// Leaving the try block or a catch block normally runs the finally
+	// block, then continues after the statement
+	var endJumps []int
+	leave := func(line uint32) {
+		if try.finally {
+			try.fastCalls = append(try.fastCalls, c.EmitWithLine(vm.OpFastCall, line, vm.UnusedOperand()))
+		}
+		endJumps = append(endJumps, c.EmitWithLine(vm.OpJmp, line, vm.UnusedOperand()))
+	}
+
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+	leave(line)
+
+	previousCatch := -1
+	for i, catchClause := range node.CatchClauses {
+		catchLine := uint32(catchClause.Token.Pos.Line)
+		pos := c.CurrentPosition()
+		if i == 0 {
+			c.tryCatch[try.region].CatchOp = pos
+		} else {
+			c.ChangeOperand(previousCatch, 2, vm.ConstOperand(uint32(pos)))
+		}
+		previousCatch = pos
+
+		// CATCH with the resolved class names ("A|B" for multi-catch)
+		catchTypes := make([]string, 0, len(catchClause.Types))
+		for _, typ := range catchClause.Types {
+			catchTypes = append(catchTypes, c.namespace.ResolveClassName(typ.String()))
+		}
+		result := vm.UnusedOperand()
+		if catchClause.Variable != nil {
+			symbol := c.variableSymbol(catchClause.Variable.Name)
+			result = vm.CVOperand(uint32(symbol.Index))
+		}
+		c.EmitWithLine(vm.OpCatch, catchLine,
+			vm.ConstOperand(uint32(c.AddConstant(strings.Join(catchTypes, "|")))),
+			vm.UnusedOperand(),
+			result)
+		if i == len(node.CatchClauses)-1 {
+			c.instructions[pos].ExtendedValue = vm.CatchLast
+		}
+
+		if err := c.Compile(catchClause.Body); err != nil {
+			return err
+		}
+		leave(catchLine)
+	}
+
+	if try.finally {
+		finallyPos := c.CurrentPosition()
+		c.tryCatch[try.region].FinallyOp = finallyPos
+		for _, pos := range try.fastCalls {
+			c.ChangeOperand(pos, 1, vm.ConstOperand(uint32(finallyPos)))
+		}
+
+		try.inFinally = true
+		if err := c.Compile(node.Finally); err != nil {
+			return err
+		}
+		c.tryCatch[try.region].FinallyEnd = c.EmitWithLine(vm.OpFastRet, line)
+	}
+
+	endPos := c.CurrentPosition()
+	for _, pos := range endJumps {
+		c.ChangeOperand(pos, 1, vm.ConstOperand(uint32(endPos)))
+	}
+	return nil
+}
+
+// leaveTries emits the code leaving the try statements of the op array
+// entered after the first depth ones and before the first top ones,
+// innermost first, for a jump or return out of them: their finally blocks
+// run, and the finally blocks being left are discarded along with the
+// exceptions they would rethrow
+func (c *Compiler) leaveTries(top, depth int, line uint32) {
+	for i := top - 1; i >= depth; i-- {
+		try := c.tryStack[i]
+		switch {
+		case try.inFinally:
+			c.EmitWithLine(vm.OpDiscardException, line)
+		case try.finally:
+			try.fastCalls = append(try.fastCalls, c.EmitWithLine(vm.OpFastCall, line, vm.UnusedOperand()))
+		}
+	}
+}
+
+// hasFinally reports whether a return from the current position runs or
+// leaves a finally block
+func (c *Compiler) hasFinally() bool {
+	for _, try := range c.tryStack {
+		if try.finally {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package compiler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// checkTable checks that the exception table entries point at the
+// instructions of their try statements
+func checkTable(t *testing.T, instructions vm.Instructions, table []types.TryCatchElement) {
+	t.Helper()
+	finally := make(map[int]bool)
+	for _, tc := range table {
+		if tc.CatchOp > 0 && instructions[tc.CatchOp].Opcode != vm.OpCatch {
+			t.Errorf("Region %+v: expected CATCH at %d, got %s", tc, tc.CatchOp, instructions[tc.CatchOp].Opcode)
+		}
+		if tc.FinallyOp > 0 && instructions[tc.FinallyEnd].Opcode != vm.OpFastRet {
+			t.Errorf("Region %+v: expected FAST_RET at %d, got %s", tc, tc.FinallyEnd, instructions[tc.FinallyEnd].Opcode)
+		}
+		finally[tc.FinallyOp] = tc.FinallyOp > 0
+	}
+	for pos, instr := range instructions {
+		if instr.Opcode == vm.OpFastCall && !finally[int(instr.Op1.Value)] {
+			t.Errorf("FAST_CALL at %d enters %d, which starts no finally block", pos, instr.Op1.Value)
+		}
+	}
+}
+
+func TestCompileTry_ExceptionTable(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+try {
+    try {
+        f();
+    } catch (A|B $e) {
+        g($e);
+    } catch (C) {
+    } finally {
+        h();
+    }
+} finally {
+    i();
+}`)
+	instructions := bytecode.Instructions
+	if len(bytecode.TryCatch) != 2 {
+		t.Fatalf("Expected 2 regions, got %+v", bytecode.TryCatch)
+	}
+
+	// The enclosing region comes first, both start at the first call
+	outer, inner := bytecode.TryCatch[0], bytecode.TryCatch[1]
+	if outer.TryOp != inner.TryOp || outer.CatchOp != 0 || outer.FinallyOp <= inner.FinallyEnd {
+		t.Errorf("Unexpected regions %+v and %+v", outer, inner)
+	}
+	checkTable(t, instructions, bytecode.TryCatch)
+
+	// The catch chain: a CATCH continues at the next one if the exception
+	// does not match, the last one rethrows it
+	first := instructions[inner.CatchOp]
+	if first.ExtendedValue&vm.CatchLast != 0 || !first.Result.IsCV() {
+		t.Errorf("Unexpected first CATCH %+v", first)
+	}
+	last := instructions[first.Op2.Value]
+	if last.Opcode != vm.OpCatch || last.ExtendedValue&vm.CatchLast == 0 || !last.Result.IsUnused() {
+		t.Errorf("Unexpected last CATCH %+v", last)
+	}
+	if types := bytecode.Constants[first.Op1.Value]; types != "A|B" {
+		t.Errorf("Expected the classes A|B, got %v", types)
+	}
+
+	if listing := Disassemble(bytecode); !strings.Contains(listing, "EXCEPTION TABLE:\n") {
+		t.Errorf("Listing does not show the exception table:\n%s", listing)
+	}
+}
+
+func TestCompileTry_LeavingFinally(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+function f() {
+    while (g()) {
+        try {
+            try {
+                continue;
+            } finally {
+                break;
+            }
+        } catch (E) {
+            return 1;
+        } finally {
+            h();
+        }
+    }
+}`)
+	fn := bytecode.Functions[0]
+	if len(fn.TryCatch) != 2 {
+		t.Fatalf("Expected 2 regions, got %+v", fn.TryCatch)
+	}
+
+	// Each jump or return runs the finally blocks it leaves, innermost
+	// first, and discards the one it leaves from
+	var got [][]vm.Opcode
+	var sequence []vm.Opcode
+	for _, instr := range fn.Instructions {
+		switch instr.Opcode {
+		case vm.OpFastCall, vm.OpDiscardException, vm.OpJmp, vm.OpReturn:
+			sequence = append(sequence, instr.Opcode)
+			if instr.Opcode == vm.OpJmp || instr.Opcode == vm.OpReturn {
+				got = append(got, sequence)
+				sequence = nil
+			}
+		default:
+			sequence = nil
+		}
+	}
+	expected := [][]vm.Opcode{
+		{vm.OpFastCall, vm.OpFastCall, vm.OpJmp},         // continue
+		{vm.OpFastCall, vm.OpJmp},                        // end of the inner try block
+		{vm.OpDiscardException, vm.OpFastCall, vm.OpJmp}, // break
+		{vm.OpFastCall, vm.OpJmp},                        // end of the outer try block
+		{vm.OpFastCall, vm.OpReturn},                     // return
+		{vm.OpFastCall, vm.OpJmp},                        // end of the catch block
+		{vm.OpJmp},                                       // loop
+		{vm.OpReturn},                                    // implicit return
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected sequences %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if len(got[i]) != len(expected[i]) {
+			t.Errorf("Sequence %d: expected %v, got %v", i, expected[i], got[i])
+			continue
+		}
+		for j := range expected[i] {
+			if got[i][j] != expected[i][j] {
+				t.Errorf("Sequence %d: expected %v, got %v", i, expected[i], got[i])
+				break
+			}
+		}
+	}
+
+	// The returned value is kept out of the way of the finally block
+	for pos, instr := range fn.Instructions {
+		if instr.Opcode == vm.OpReturn && !instr.Op1.IsUnused() && instr.Op1 == vm.TmpVarOperand(0) {
+			t.Errorf("RETURN at %d returns %v", pos, instr.Op1)
+		}
+	}
+	checkTable(t, fn.Instructions, fn.TryCatch)
+}
+
+func TestCompileTry_Optimized(t *testing.T) {
+	// Compacting the peephole optimizer's NOPs relocates the regions
+	for _, level := range []OptimizationLevel{OptimizeNone, OptimizePeephole, OptimizeDataFlow} {
+		script, err := ScriptCompiler(level)("test.php", []byte(`<?php
+1;
+try {
+    2;
+    f();
+} catch (E $e) {
+    3;
+} finally {
+    4;
+}`))
+		if err != nil {
+			t.Fatalf("Compilation failed: %v", err)
+		}
+		if len(script.TryCatch) != 1 {
+			t.Fatalf("Level %d: expected a region, got %+v", level, script.TryCatch)
+		}
+		tc := script.TryCatch[0]
+		if tc.TryOp >= len(script.Instructions) || script.Instructions[tc.TryOp].Opcode == vm.OpNop {
+			t.Errorf("Level %d: region %+v does not start at an instruction", level, tc)
+		}
+		checkTable(t, script.Instructions, script.TryCatch)
+	}
+}
+
+func TestCompileTry_Run(t *testing.T) {
+	script, err := CompileScript("test.php", []byte(`<?php
+try {
+    try {
+        echo "try\n";
+        intdiv(1, 0);
+        echo "unreachable\n";
+    } catch (TypeError $e) {
+        echo "type\n";
+    } finally {
+        echo "inner finally\n";
+    }
+} catch (DivisionByZeroError $e) {
+    echo "outer catch\n";
+    try {
+        intdiv(1, 0);
+    } catch (Error) {
+        echo "in catch\n";
+    }
+} finally {
+    echo "outer finally\n";
+}
+try {
+    try {
+        intdiv(1, 0);
+    } finally {
+        echo "discard\n";
+        return;
+    }
+} finally {
+    echo "still runs\n";
+}
+echo "unreachable\n";`))
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	machine := vm.New()
+	machine.SetOutputWriter(&out)
+	if err := machine.ExecuteScript(script); err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	expected := "try\ninner finally\nouter catch\nin catch\nouter finally\ndiscard\nstill runs\n"
+	if out.String() != expected {
+		t.Errorf("Expected output\n%s\ngot\n%s", expected, out.String())
+	}
+}
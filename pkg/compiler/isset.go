@@ -0,0 +1,265 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// isset(), empty() and unset()
+// ========================================
+
+// compileIsset compiles isset($a, $b, ...): the variables are checked in
+// turn and the first one not set makes the result false. The result is
+// left in temp 0.
+func (c *Compiler) compileIsset(node *ast.IssetExpression) error {
+	line := uint32(node.Token.Pos.Line)
+
+	var ends []int
+	for i, variable := range node.Variables {
+		if err := c.compileIssetCheck(variable, 0); err != nil {
+			return err
+		}
+		if i < len(node.Variables)-1 {
+			ends = append(ends, c.EmitWithLine(vm.OpJmpZ, line, vm.TmpVarOperand(0), vm.UnusedOperand(), vm.UnusedOperand()))
+		}
+	}
+
+	end := vm.ConstOperand(uint32(c.CurrentPosition()))
+	for _, pos := range ends {
+		c.ChangeOperand(pos, 2, end)
+	}
+	return nil
+}
+
+// compileEmpty compiles empty(expr): variables, elements and properties
+// are checked like isset() does, without notices; other expressions are
+// evaluated and negated. The result is left in temp 0.
+func (c *Compiler) compileEmpty(node *ast.EmptyExpression) error {
+	switch node.Expr.(type) {
+	case *ast.Variable, *ast.VariableVariable, *ast.IndexExpression, *ast.PropertyExpression,
+		*ast.NullsafePropertyExpression, *ast.StaticPropertyExpression:
+		return c.compileIssetCheck(node.Expr, vm.IssetIsEmpty)
+	}
+
+	if err := c.Compile(node.Expr); err != nil {
+		return err
+	}
+	c.EmitWithLine(vm.OpBoolNot, uint32(node.Token.Pos.Line), vm.TmpVarOperand(0), vm.UnusedOperand(), vm.TmpVarOperand(0))
+	return nil
+}
+
+// compileIssetCheck emits the ISSET_ISEMPTY_* check of one variable,
+// element or property into temp 0. The containers of elements and
+// properties are fetched quietly; a short-circuited nullsafe chain makes
+// the variable not set. flags is vm.IssetIsEmpty for empty().
+func (c *Compiler) compileIssetCheck(expr ast.Expr, flags uint32) error {
+	var (
+		jumps []int
+		err   error
+	)
+
+	switch node := expr.(type) {
+	case *ast.Variable:
+		c.EmitWithExtended(vm.OpIssetIsemptyCV, uint32(node.Token.Pos.Line), flags,
+			c.variableOperand(node),
+			vm.UnusedOperand(),
+			vm.TmpVarOperand(0))
+
+	case *ast.VariableVariable:
+		if err := c.Compile(node.Name); err != nil {
+			return err
+		}
+		c.EmitWithExtended(vm.OpIssetIsemptyVar, uint32(node.Token.Pos.Line), flags,
+			vm.TmpVarOperand(0),
+			vm.UnusedOperand(),
+			vm.TmpVarOperand(0))
+
+	case *ast.IndexExpression:
+		if node.Index == nil {
+			return fmt.Errorf("cannot use [] for reading")
+		}
+		if err := c.compileChain(node.Left, true, &jumps); err != nil {
+			return err
+		}
+		err = c.emitIssetMember(vm.OpIssetIsemptyDimObj, node.Index, false, flags, uint32(node.Token.Pos.Line))
+
+	case *ast.PropertyExpression:
+		if err := c.compileChain(node.Object, true, &jumps); err != nil {
+			return err
+		}
+		err = c.emitIssetMember(vm.OpIssetIsemptyPropObj, node.Property, true, flags, uint32(node.Token.Pos.Line))
+
+	case *ast.NullsafePropertyExpression:
+		if err := c.compileChain(node.Object, true, &jumps); err != nil {
+			return err
+		}
+		c.emitJmpNull(&jumps, uint32(node.Token.Pos.Line))
+		err = c.emitIssetMember(vm.OpIssetIsemptyPropObj, node.Property, true, flags, uint32(node.Token.Pos.Line))
+
+	case *ast.StaticPropertyExpression:
+		class, name, err := c.compileStaticMember(node)
+		if err != nil {
+			return err
+		}
+		c.EmitWithExtended(vm.OpIssetIsemptyStaticProp, uint32(node.Token.Pos.Line), flags, class, name, vm.TmpVarOperand(0))
+
+	default:
+		return fmt.Errorf("cannot use isset() on the result of an expression (you can use \"null !== expression\" instead)")
+	}
+	if err != nil {
+		return err
+	}
+
+	shortCircuit := vm.JmpNullIsset
+	if flags&vm.IssetIsEmpty != 0 {
+		shortCircuit = vm.JmpNullEmpty
+	}
+	c.patchChainJumps(jumps, shortCircuit)
+	return nil
+}
+
+// emitIssetMember emits the check of an element or property of the
+// container in temp 0
+func (c *Compiler) emitIssetMember(opcode vm.Opcode, member ast.Expr, property bool, flags uint32, line uint32) error {
+	container, key, err := c.compileMember(vm.TmpVarOperand(0), member, property, line)
+	if err != nil {
+		return err
+	}
+	c.EmitWithExtended(opcode, line, flags, container, key, vm.TmpVarOperand(0))
+	return nil
+}
+
+// compileStaticMember returns the class and property name operands of a
+// static property. Named classes are resolved at compile time; self,
+// parent and static are resolved by the VM. The name of Foo::$$prop is
+// the value of $prop.
+func (c *Compiler) compileStaticMember(node *ast.StaticPropertyExpression) (vm.Operand, vm.Operand, error) {
+	line := uint32(node.Token.Pos.Line)
+
+	class := vm.TmpVarOperand(0)
+	if ident, ok := node.Class.(*ast.Identifier); ok {
+		class = vm.ConstOperand(uint32(c.AddConstant(c.resolveClassName(ident.Value))))
+	} else if err := c.Compile(node.Class); err != nil {
+		return vm.Operand{}, vm.Operand{}, err
+	}
+
+	if variable, ok := node.Property.(*ast.Variable); ok {
+		return class, vm.ConstOperand(uint32(c.AddConstant(variable.Name))), nil
+	}
+	if variable, ok := node.Property.(*ast.VariableVariable); ok {
+		return c.compileMember(class, variable.Name, false, line)
+	}
+	return c.compileMember(class, node.Property, false, line)
+}
+
+// compileUnset compiles unset($a, $b['k'], $c->p, ...). Containers of
+// unset elements are fetched in place, so unsetting a nested element
+// modifies the variable holding it.
+func (c *Compiler) compileUnset(node *ast.UnsetStatement) error {
+	line := uint32(node.Token.Pos.Line)
+
+	for _, variable := range node.Variables {
+		if hasNullsafe(variable) {
+			return fmt.Errorf("cannot use nullsafe operator in write context")
+		}
+
+		switch target := variable.(type) {
+		case *ast.Variable:
+			c.EmitWithLine(vm.OpUnsetCV, line, c.variableOperand(target), vm.UnusedOperand(), vm.UnusedOperand())
+
+		case *ast.VariableVariable:
+			if err := c.Compile(target.Name); err != nil {
+				return err
+			}
+			c.EmitWithExtended(vm.OpUnsetVar, line, vm.FetchByName, vm.TmpVarOperand(0), vm.UnusedOperand(), vm.UnusedOperand())
+
+		case *ast.IndexExpression:
+			if target.Index == nil {
+				return fmt.Errorf("cannot use [] for unsetting")
+			}
+			if err := c.emitUnsetMember(vm.OpUnsetDim, target.Left, target.Index, false, line); err != nil {
+				return err
+			}
+
+		case *ast.PropertyExpression:
+			if err := c.emitUnsetMember(vm.OpUnsetObj, target.Object, target.Property, true, line); err != nil {
+				return err
+			}
+
+		case *ast.StaticPropertyExpression:
+			class, name, err := c.compileStaticMember(target)
+			if err != nil {
+				return err
+			}
+			c.EmitWithLine(vm.OpUnsetStaticProp, line, class, name, vm.UnusedOperand())
+
+		default:
+			return fmt.Errorf("cannot unset %s", variable.String())
+		}
+	}
+	return nil
+}
+
+// emitUnsetMember emits the UNSET_DIM or UNSET_OBJ of an element or
+// property of a container
+func (c *Compiler) emitUnsetMember(opcode vm.Opcode, containerExpr, member ast.Expr, property bool, line uint32) error {
+	container, err := c.compileUnsetContainer(containerExpr)
+	if err != nil {
+		return err
+	}
+	container, key, err := c.compileMember(container, member, property, line)
+	if err != nil {
+		return err
+	}
+	c.EmitWithLine(opcode, line, container, key, vm.UnusedOperand())
+	return nil
+}
+
+// compileUnsetContainer returns the operand of the container of an unset
+// element or property: a variable itself ($this and $GLOBALS are fetched
+// into temp 0), or an element or property fetched in place into a new
+// temporary
+func (c *Compiler) compileUnsetContainer(expr ast.Expr) (vm.Operand, error) {
+	var (
+		fetch    vm.Opcode
+		parent   ast.Expr
+		member   ast.Expr
+		property bool
+		line     uint32
+	)
+
+	switch node := expr.(type) {
+	case *ast.Variable:
+		if node.Name != "GLOBALS" && node.Name != "this" {
+			return c.variableOperand(node), nil
+		}
+		// $this is bound to no variable, and objects are handles
+		return vm.TmpVarOperand(0), c.Compile(node)
+	case *ast.IndexExpression:
+		if node.Index == nil {
+			return vm.Operand{}, fmt.Errorf("cannot use [] for unsetting")
+		}
+		fetch, parent, member, line = vm.OpFetchDimUnset, node.Left, node.Index, uint32(node.Token.Pos.Line)
+	case *ast.PropertyExpression:
+		fetch, parent, member, property, line = vm.OpFetchObjUnset, node.Object, node.Property, true, uint32(node.Token.Pos.Line)
+	default:
+		return vm.Operand{}, fmt.Errorf("cannot use %s in write context", expr.String())
+	}
+
+	container, err := c.compileUnsetContainer(parent)
+	if err != nil {
+		return vm.Operand{}, err
+	}
+	container, key, err := c.compileMember(container, member, property, line)
+	if err != nil {
+		return vm.Operand{}, err
+	}
+	// A temporary of its own, as a key computed into temp 0 would need a
+	// copy of the container
+	fetched := c.newTemp()
+	c.EmitWithLine(fetch, line, container, key, fetched)
+	return fetched, nil
+}
@@ -0,0 +1,77 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/lexer"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// Break and Continue
+// ========================================
+
+// compileJump compiles a break or continue statement out of depth
+// enclosing loops or switch statements (1 without a depth operand). The
+// code leaving the statements in between runs first, innermost first:
+// their finally blocks and the iterators of the foreach loops left. The
+// target foreach frees its own iterator at the end of the loop, which is
+// where break jumps to.
+func (c *Compiler) compileJump(token lexer.Token, depthExpr ast.Expr, isContinue bool) error {
+	keyword := "break"
+	if isContinue {
+		keyword = "continue"
+	}
+
+	// The depth must be a positive integer literal, validated at compile
+	// time like PHP does
+	depth := 1
+	if depthExpr != nil {
+		literal, ok := depthExpr.(*ast.IntegerLiteral)
+		if !ok {
+			return fmt.Errorf("'%s' operator with non-integer operand is no longer supported", keyword)
+		}
+		if literal.Value < 1 {
+			return fmt.Errorf("'%s' operator accepts only positive integers", keyword)
+		}
+		if literal.Value > int64(len(c.loopStack)) {
+			if !c.InLoop() {
+				return fmt.Errorf("'%s' not in the 'loop' or 'switch' context", keyword)
+			}
+			return fmt.Errorf("cannot '%s' %d levels", keyword, literal.Value)
+		}
+		depth = int(literal.Value)
+	}
+	if !c.InLoop() {
+		return fmt.Errorf("'%s' not in the 'loop' or 'switch' context", keyword)
+	}
+
+	line := uint32(token.Pos.Line)
+	target := c.loopStack[len(c.loopStack)-depth]
+	tries := len(c.tryStack)
+	for i := len(c.loopStack) - 1; ; i-- {
+		loop := c.loopStack[i]
+		c.leaveTries(tries, loop.tryDepth, line)
+		tries = loop.tryDepth
+		if loop == target {
+			break
+		}
+		if loop.iterator != nil {
+			c.EmitWithLine(vm.OpFeFree, line, *loop.iterator)
+		}
+	}
+
+	// JMP with placeholder (will be patched by ExitLoop); continue
+	// targeting a switch is equivalent to break
+	jmpPos := c.EmitWithLine(vm.OpJmp, line,
+		vm.UnusedOperand(),
+		vm.UnusedOperand(),
+		vm.UnusedOperand())
+	if isContinue && !target.isSwitch {
+		target.continueJumps = append(target.continueJumps, jmpPos)
+	} else {
+		target.breakJumps = append(target.breakJumps, jmpPos)
+	}
+	return nil
+}
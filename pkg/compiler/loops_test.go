@@ -0,0 +1,153 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// jumpAfter returns the position of the first JMP at or after pos and the
+// opcodes emitted from pos up to it
+func jumpAfter(instructions vm.Instructions, pos int) (int, []vm.Opcode) {
+	var ops []vm.Opcode
+	for ; instructions[pos].Opcode != vm.OpJmp; pos++ {
+		ops = append(ops, instructions[pos].Opcode)
+	}
+	return pos, ops
+}
+
+// positionOf returns the position of the nth (from 0) instruction with the
+// opcode
+func positionOf(instructions vm.Instructions, op vm.Opcode, n int) int {
+	for pos, instr := range instructions {
+		if instr.Opcode == op {
+			if n == 0 {
+				return pos
+			}
+			n--
+		}
+	}
+	return -1
+}
+
+func TestCompileBreak_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"<?php break;", "'break' not in the 'loop' or 'switch' context"},
+		{"<?php continue 2;", "'continue' not in the 'loop' or 'switch' context"},
+		{"<?php while (true) { break 0; }", "'break' operator accepts only positive integers"},
+		{"<?php while (true) { break 2; }", "cannot 'break' 2 levels"},
+		{"<?php foreach ($a as $x) { switch ($x) { default: continue 3; } }", "cannot 'continue' 3 levels"},
+		// Loops do not extend into function bodies
+		{"<?php while (true) { function f() { break; } }", "'break' not in the 'loop' or 'switch' context"},
+	}
+
+	for _, tt := range tests {
+		_, err := compileSource(tt.input)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.err, err)
+		}
+	}
+}
+
+func TestCompileBreak_Foreach(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+foreach ($a as $x) {
+    foreach ($b as $y) {
+        break 2;
+    }
+}
+`)
+	instructions := bytecode.Instructions
+
+	// The inner iterator is freed before jumping to the end of the outer
+	// loop, whose FE_FREE comes last
+	innerFetch := positionOf(instructions, vm.OpFeFetchR, 1)
+	jmp, ops := jumpAfter(instructions, innerFetch+1)
+	if len(ops) == 0 || ops[len(ops)-1] != vm.OpFeFree {
+		t.Fatalf("Expected FE_FREE before the break, got %v", ops)
+	}
+	outerFree := positionOf(instructions, vm.OpFeFree, 2)
+	if outerFree < 0 || instructions[jmp].Op1.Value != uint32(outerFree) {
+		t.Errorf("Expected break 2 to jump to the outer FE_FREE at %d, got %d", outerFree, instructions[jmp].Op1.Value)
+	}
+}
+
+func TestCompileContinue_Switch(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+foreach ($a as $x) {
+    switch ($x) {
+        case 1:
+            continue 2;
+        case 2:
+            continue;
+    }
+    echo $x;
+}
+`)
+	instructions := bytecode.Instructions
+	fetch := positionOf(instructions, vm.OpFeFetchR, 0)
+	end := instructions[positionOf(instructions, vm.OpJmp, 0)].Op1.Value
+
+	// continue 2 continues the foreach without freeing its iterator
+	continue2 := positionOf(instructions, vm.OpJmp, 1)
+	if target := instructions[continue2].Op1.Value; target != uint32(fetch) {
+		t.Errorf("Expected continue 2 to jump to FE_FETCH at %d, got %d", fetch, target)
+	}
+	if op := instructions[continue2-1].Opcode; op == vm.OpFeFree {
+		t.Errorf("Expected no FE_FREE before continue 2")
+	}
+
+	// continue targeting the switch is equivalent to break, jumping where
+	// the unmatched switch does
+	continue1 := positionOf(instructions, vm.OpJmp, 2)
+	if target := instructions[continue1].Op1.Value; target != end {
+		t.Errorf("Expected continue to jump past the switch to %d, got %d", end, target)
+	}
+}
+
+func TestCompileBreak_Finally(t *testing.T) {
+	bytecode := parseAndCompile(t, `<?php
+while (true) {
+    try {
+        foreach ($a as $x) {
+            try {
+                break 2;
+            } finally {
+                f();
+            }
+        }
+    } finally {
+        g();
+    }
+}
+`)
+	instructions := bytecode.Instructions
+	checkTable(t, instructions, bytecode.TryCatch)
+
+	// The finally blocks and the iterator are left innermost first
+	fetch := positionOf(instructions, vm.OpFeFetchR, 0)
+	_, ops := jumpAfter(instructions, fetch+1)
+	want := []vm.Opcode{vm.OpFastCall, vm.OpFeFree, vm.OpFastCall}
+	if len(ops) < len(want) {
+		t.Fatalf("Expected %v before the break, got %v", want, ops)
+	}
+	for i, op := range ops[len(ops)-len(want):] {
+		if op != want[i] {
+			t.Fatalf("Expected %v before the break, got %v", want, ops)
+		}
+	}
+}
+
+func TestRun_BreakContinueLevels(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`for ($i = 0; $i < 3; $i++) { for ($j = 0; $j < 3; $j++) { if ($j == 1) { break 2; } echo "$i$j "; } }`, "00 "},
+		{`for ($i = 0; $i < 3; $i++) { for ($j = 0; $j < 3; $j++) { if ($j == 1) { continue 2; } echo "$i$j "; } }`, "00 10 20 "},
+		{`foreach ([1, 2] as $a) { foreach ([1, 2, 3] as $b) { if ($b == 2) { continue 2; } echo "$a$b "; } }`, "11 21 "},
+		{`foreach ([1, 2] as $a) { foreach ([1, 2, 3] as $b) { if ($a == 2) { break 2; } echo "$a$b "; } } echo "end";`, "11 12 13 end"},
+		{`$i = 0; while (true) { while (true) { $i++; if ($i > 3) { break 2; } continue 2; } } echo $i;`, "4"},
+		{`foreach ([1, 2, 3] as $v) { switch ($v) { case 2: continue 2; default: echo $v; } echo "-"; }`, "1-3-"},
+	})
+}
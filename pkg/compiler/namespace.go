@@ -0,0 +1,291 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// Namespace Name Resolution
+// ========================================
+
+// NamespaceContext holds the current namespace and its imports (use statements).
+// Names are resolved following PHP's rules:
+//
+//   - fully qualified (\A\B): used as written
+//   - namespace-relative (namespace\A): prefixed with the current namespace
+//   - qualified (A\B): the first segment is resolved through class imports,
+//     otherwise prefixed with the current namespace
+//   - unqualified (A): resolved through the imports of the matching kind;
+//     classes are otherwise prefixed with the current namespace, while
+//     functions and constants fall back to the global name at runtime
+type NamespaceContext struct {
+	// name is the current namespace ("" for the global namespace)
+	name string
+
+	// Import tables, keyed by alias. Class and function aliases are
+	// case-insensitive (lowercased keys), constant aliases are case-sensitive.
+	classes   map[string]string
+	functions map[string]string
+	constants map[string]string
+}
+
+// NewNamespaceContext creates a context for the given namespace with no imports
+func NewNamespaceContext(name string) *NamespaceContext {
+	return &NamespaceContext{
+		name:      strings.Trim(name, "\\"),
+		classes:   make(map[string]string),
+		functions: make(map[string]string),
+		constants: make(map[string]string),
+	}
+}
+
+// Name returns the current namespace name
+func (ns *NamespaceContext) Name() string {
+	return ns.name
+}
+
+// AddUse registers an import. kind is "" for classes/namespaces, "function" or "const".
+func (ns *NamespaceContext) AddUse(kind, name, alias string) error {
+	name = strings.TrimPrefix(name, "\\")
+	if alias == "" {
+		alias = lastSegment(name)
+	}
+
+	var table map[string]string
+	key := strings.ToLower(alias)
+	switch kind {
+	case "":
+		table = ns.classes
+		if isSpecialClassName(alias) {
+			return fmt.Errorf("cannot use %s as %s because '%s' is a special class name", name, alias, alias)
+		}
+	case "function":
+		table = ns.functions
+	case "const":
+		table = ns.constants
+		key = alias
+	default:
+		return fmt.Errorf("unknown use kind: %s", kind)
+	}
+
+	if existing, ok := table[key]; ok && !strings.EqualFold(existing, name) {
+		return fmt.Errorf("cannot use %s as %s because the name is already in use", name, alias)
+	}
+	table[key] = name
+	return nil
+}
+
+// ResolveClassName returns the fully qualified name of a class reference.
+// self, parent and static are returned unchanged.
+func (ns *NamespaceContext) ResolveClassName(name string) string {
+	if isSpecialClassName(name) {
+		return strings.ToLower(name)
+	}
+	if resolved, ok := ns.resolveQualified(name); ok {
+		return resolved
+	}
+	if imported, ok := ns.classes[strings.ToLower(name)]; ok {
+		return imported
+	}
+	return ns.prefix(name)
+}
+
+// ResolveFunctionName returns the fully qualified name of a function call and,
+// for unqualified names inside a namespace, the global name to fall back to
+// ("" when there is no fallback)
+func (ns *NamespaceContext) ResolveFunctionName(name string) (string, string) {
+	if resolved, ok := ns.resolveQualified(name); ok {
+		return resolved, ""
+	}
+	if imported, ok := ns.functions[strings.ToLower(name)]; ok {
+		return imported, ""
+	}
+	if ns.name == "" {
+		return name, ""
+	}
+	return ns.prefix(name), name
+}
+
+// ResolveConstantName returns the fully qualified name of a constant and,
+// for unqualified names inside a namespace, the global name to fall back to
+// ("" when there is no fallback)
+func (ns *NamespaceContext) ResolveConstantName(name string) (string, string) {
+	if resolved, ok := ns.resolveQualified(name); ok {
+		return resolved, ""
+	}
+	if imported, ok := ns.constants[name]; ok {
+		return imported, ""
+	}
+	if ns.name == "" {
+		return name, ""
+	}
+	return ns.prefix(name), name
+}
+
+// resolveQualified resolves fully qualified, namespace-relative and qualified
+// names. It returns false for unqualified names.
+func (ns *NamespaceContext) resolveQualified(name string) (string, bool) {
+	if strings.HasPrefix(name, "\\") {
+		return name[1:], true
+	}
+
+	if len(name) > len("namespace\\") && strings.EqualFold(name[:len("namespace\\")], "namespace\\") {
+		return ns.prefix(name[len("namespace\\"):]), true
+	}
+
+	idx := strings.Index(name, "\\")
+	if idx < 0 {
+		return "", false
+	}
+
+	// The first segment of a qualified name may be an imported namespace
+	if imported, ok := ns.classes[strings.ToLower(name[:idx])]; ok {
+		return imported + name[idx:], true
+	}
+	return ns.prefix(name), true
+}
+
+// prefix prepends the current namespace to a name
+func (ns *NamespaceContext) prefix(name string) string {
+	if ns.name == "" {
+		return name
+	}
+	return ns.name + "\\" + name
+}
+
+// lastSegment returns the part of a qualified name after the last backslash
+func lastSegment(name string) string {
+	if idx := strings.LastIndex(name, "\\"); idx >= 0 {
+		return name[idx+1:]
+	}
+	return name
+}
+
+// isSpecialClassName reports whether name is self, parent or static
+func isSpecialClassName(name string) bool {
+	switch strings.ToLower(name) {
+	case "self", "parent", "static":
+		return true
+	}
+	return false
+}
+
+// ========================================
+// Namespace Compilation
+// ========================================
+
+// compileNamespace switches the current namespace. The braced form scopes the
+// namespace (and its imports) to the block; the statement form applies until
+// the next namespace declaration.
+func (c *Compiler) compileNamespace(node *ast.NamespaceStatement) error {
+	c.namespace = NewNamespaceContext(node.Name)
+	if node.Body == nil {
+		return nil
+	}
+
+	err := c.Compile(node.Body)
+	c.namespace = NewNamespaceContext("")
+	return err
+}
+
+// compileClassRef compiles a class reference (new Foo, Foo::bar(), instanceof Foo).
+// Class names are resolved to their fully qualified form; dynamic class
+// expressions are compiled as-is.
+func (c *Compiler) compileClassRef(expr ast.Expr) error {
+	ident, ok := expr.(*ast.Identifier)
+	if !ok {
+		return c.Compile(expr)
+	}
+
+	constIdx := c.AddConstant(c.resolveClassName(ident.Value))
+	c.EmitWithLine(vm.OpQMAssign, uint32(ident.Token.Pos.Line),
+		vm.ConstOperand(uint32(constIdx)),
+		vm.UnusedOperand(),
+		vm.TmpVarOperand(0))
+	return nil
+}
+
+// compileClassNameConstant compiles Foo::class. Named classes are resolved at
+// compile time; self, parent, static and $obj::class are resolved at runtime.
+func (c *Compiler) compileClassNameConstant(node *ast.StaticPropertyExpression) error {
+	line := uint32(node.Token.Pos.Line)
+
+	if ident, ok := node.Class.(*ast.Identifier); ok {
+		name := c.namespace.ResolveClassName(ident.Value)
+		if !isSpecialClassName(name) {
+			c.EmitWithLine(vm.OpQMAssign, line,
+				vm.ConstOperand(uint32(c.AddConstant(name))),
+				vm.UnusedOperand(),
+				vm.TmpVarOperand(0))
+			return nil
+		}
+	}
+
+	if err := c.compileClassRef(node.Class); err != nil {
+		return err
+	}
+	c.EmitWithLine(vm.OpFetchClassName, line,
+		vm.TmpVarOperand(0),
+		vm.UnusedOperand(),
+		vm.TmpVarOperand(0))
+	return nil
+}
+
+// compileClassConstant compiles Foo::BAR, a class constant or enum case,
+// into temp 0. Named classes are resolved at compile time; self, parent
+// and static are resolved at runtime.
+func (c *Compiler) compileClassConstant(node *ast.StaticPropertyExpression, name *ast.Identifier) error {
+	class := vm.TmpVarOperand(0)
+	if ident, ok := node.Class.(*ast.Identifier); ok {
+		class = vm.ConstOperand(uint32(c.AddConstant(c.resolveClassName(ident.Value))))
+	} else if err := c.Compile(node.Class); err != nil {
+		return err
+	}
+
+	c.EmitWithLine(vm.OpFetchClassConstant, uint32(node.Token.Pos.Line),
+		class,
+		vm.ConstOperand(uint32(c.AddConstant(name.Value))),
+		vm.TmpVarOperand(0))
+	return nil
+}
+
+// compileInitFcall emits the INIT_* opcode of a function call. Calls by name
+// are resolved against the current namespace; unqualified names inside a
+// namespace become INIT_NS_FCALL_BY_NAME, which falls back to the global
+// function at runtime. Other callees are called by value with
+// INIT_DYNAMIC_CALL.
+func (c *Compiler) compileInitFcall(node *ast.CallExpression, numArgs int) error {
+	line := uint32(node.Token.Pos.Line)
+
+	ident, ok := node.Function.(*ast.Identifier)
+	if !ok {
+		// Dynamic call: $f(), $obj->getCallback()(), 'Class::method'()
+		if err := c.Compile(node.Function); err != nil {
+			return err
+		}
+		c.EmitWithLine(vm.OpInitDynamicCall, line,
+			vm.TmpVarOperand(0),
+			vm.ConstOperand(uint32(numArgs)), // Argument count
+			vm.UnusedOperand())
+		return nil
+	}
+
+	name, fallback := c.namespace.ResolveFunctionName(ident.Value)
+	if fallback != "" {
+		c.EmitWithLine(vm.OpInitNsFcallByName, line,
+			vm.ConstOperand(uint32(c.AddConstant(name))),
+			vm.ConstOperand(uint32(c.AddConstant(fallback))),
+			vm.UnusedOperand())
+		return nil
+	}
+
+	c.EmitWithLine(vm.OpInitFcallByName, line,
+		vm.ConstOperand(uint32(c.AddConstant(name))),
+		vm.ConstOperand(uint32(numArgs)), // Argument count
+		vm.UnusedOperand())
+	return nil
+}
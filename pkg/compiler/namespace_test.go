@@ -0,0 +1,173 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// Namespace Resolution Tests
+// ========================================
+
+func newTestNamespace(t *testing.T) *NamespaceContext {
+	t.Helper()
+	ns := NewNamespaceContext("App\\Http")
+	uses := []struct{ kind, name, alias string }{
+		{"", "Vendor\\Lib\\Client", ""},
+		{"", "Vendor\\Models", "M"},
+		{"function", "Vendor\\Lib\\helper", ""},
+		{"const", "Vendor\\Lib\\VERSION", "V"},
+	}
+	for _, use := range uses {
+		if err := ns.AddUse(use.kind, use.name, use.alias); err != nil {
+			t.Fatalf("AddUse(%q, %q, %q): %v", use.kind, use.name, use.alias, err)
+		}
+	}
+	return ns
+}
+
+func TestResolveClassName(t *testing.T) {
+	ns := newTestNamespace(t)
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"\\DateTime", "DateTime"},
+		{"Request", "App\\Http\\Request"},
+		{"Client", "Vendor\\Lib\\Client"},
+		{"client", "Vendor\\Lib\\Client"},
+		{"M\\User", "Vendor\\Models\\User"},
+		{"Sub\\Thing", "App\\Http\\Sub\\Thing"},
+		{"namespace\\Thing", "App\\Http\\Thing"},
+		{"self", "self"},
+		{"Static", "static"},
+	}
+
+	for _, tt := range tests {
+		if got := ns.ResolveClassName(tt.name); got != tt.expected {
+			t.Errorf("ResolveClassName(%q) = %q, want %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestResolveFunctionAndConstantNames(t *testing.T) {
+	ns := newTestNamespace(t)
+
+	tests := []struct {
+		resolve  func(string) (string, string)
+		name     string
+		expected string
+		fallback string
+	}{
+		{ns.ResolveFunctionName, "strlen", "App\\Http\\strlen", "strlen"},
+		{ns.ResolveFunctionName, "\\strlen", "strlen", ""},
+		{ns.ResolveFunctionName, "helper", "Vendor\\Lib\\helper", ""},
+		{ns.ResolveFunctionName, "M\\make", "Vendor\\Models\\make", ""},
+		{ns.ResolveConstantName, "PHP_EOL", "App\\Http\\PHP_EOL", "PHP_EOL"},
+		{ns.ResolveConstantName, "V", "Vendor\\Lib\\VERSION", ""},
+		{ns.ResolveConstantName, "v", "App\\Http\\v", "v"},
+	}
+
+	for _, tt := range tests {
+		name, fallback := tt.resolve(tt.name)
+		if name != tt.expected || fallback != tt.fallback {
+			t.Errorf("resolve(%q) = (%q, %q), want (%q, %q)", tt.name, name, fallback, tt.expected, tt.fallback)
+		}
+	}
+
+	global := NewNamespaceContext("")
+	if name, fallback := global.ResolveFunctionName("strlen"); name != "strlen" || fallback != "" {
+		t.Errorf("global namespace should not need a fallback, got (%q, %q)", name, fallback)
+	}
+}
+
+func TestAddUseConflicts(t *testing.T) {
+	ns := newTestNamespace(t)
+
+	if err := ns.AddUse("", "Other\\Client", ""); err == nil {
+		t.Error("Expected error when importing a second class named Client")
+	}
+	if err := ns.AddUse("", "Vendor\\Lib\\Client", ""); err != nil {
+		t.Errorf("Re-importing the same class should be allowed: %v", err)
+	}
+	if err := ns.AddUse("", "Foo\\Bar", "static"); err == nil {
+		t.Error("Expected error when aliasing a class to 'static'")
+	}
+}
+
+func TestCompileNamespacedDeclarationsAndCalls(t *testing.T) {
+	input := `<?php
+namespace App\Models;
+
+use Vendor\Base\Model;
+use function Vendor\Util\format;
+
+function helper() { return 1; }
+class User extends Model {}
+
+$u = new User();
+$name = User::class;
+format($u);
+strlen($name);
+\strtoupper($name);
+`
+	bytecode := parseAndCompile(t, input)
+
+	constants := make(map[interface{}]bool)
+	for _, c := range bytecode.Constants {
+		constants[c] = true
+	}
+	for _, expected := range []string{
+		"App\\Models\\helper",
+		"App\\Models\\User",
+		"Vendor\\Base\\Model",
+		"Vendor\\Util\\format",
+		"App\\Models\\strlen",
+		"strtoupper",
+	} {
+		if !constants[expected] {
+			t.Errorf("Expected constant %q in %v", expected, bytecode.Constants)
+		}
+	}
+
+	var nsCalls, calls int
+	for _, instr := range bytecode.Instructions {
+		switch instr.Opcode {
+		case vm.OpInitNsFcallByName:
+			nsCalls++
+			if fallback := bytecode.Constants[instr.Op2.Value]; fallback != "strlen" {
+				t.Errorf("Expected global fallback 'strlen', got %v", fallback)
+			}
+		case vm.OpInitFcallByName:
+			calls++
+		}
+	}
+	if nsCalls != 1 || calls != 2 {
+		t.Errorf("Expected 1 INIT_NS_FCALL_BY_NAME and 2 INIT_FCALL_BY_NAME, got %d and %d", nsCalls, calls)
+	}
+}
+
+func TestCompileBracedNamespaceScopesImports(t *testing.T) {
+	input := `<?php
+namespace A {
+    use Lib\Thing;
+    $x = new Thing();
+}
+namespace {
+    $y = new Thing();
+}
+`
+	bytecode := parseAndCompile(t, input)
+
+	var created []string
+	for _, instr := range bytecode.Instructions {
+		if instr.Opcode == vm.OpNew {
+			created = append(created, bytecode.Constants[instr.Op1.Value].(string))
+		}
+	}
+	if len(created) != 2 || created[0] != "Lib\\Thing" || created[1] != "Thing" {
+		t.Errorf("Expected [Lib\\Thing Thing], got %v", created)
+	}
+}
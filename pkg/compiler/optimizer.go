@@ -0,0 +1,373 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// Optimizer
+// ========================================
+
+// OptimizationLevel selects the passes run over an op array once code
+// generation has finished it
+type OptimizationLevel int
+
+const (
+	// OptimizeNone keeps the instructions as emitted (-O0)
+	OptimizeNone OptimizationLevel = iota
+
+	// OptimizePeephole runs the peephole passes (-O1)
+	OptimizePeephole
+
+	// OptimizeDataFlow also runs the passes working on the SSA form:
+	// removal of redundant conversions, specialization of operations on
+	// inferred types and dead store elimination (-O2)
+	OptimizeDataFlow
+)
+
+// maxPeepholeRounds bounds the rounds of peephole passes; a pass can
+// enable the others, so they run until none changes anything
+const maxPeepholeRounds = 8
+
+// SetOptimizationLevel sets the passes run over the op arrays compiled
+func (c *Compiler) SetOptimizationLevel(level OptimizationLevel) {
+	c.optimization = level
+}
+
+// optimize runs the passes of the optimization level over the op array
+// being emitted into fn, nil for the main op array. Jumps are patched by
+// then, so passes may move instructions.
+func (c *Compiler) optimize(fn *vm.CompiledFunction) {
+	if c.optimization >= OptimizePeephole {
+		c.instructions = optimizePeephole(c.instructions, c.constants, c.tryCatch)
+	}
+	if c.optimization >= OptimizeDataFlow {
+		c.instructions = optimizeDataFlow(c.instructions, c.constants, fn)
+	}
+	if c.optimization >= OptimizePeephole && fn != nil {
+		markSelfTailCalls(c.instructions, c.constants, c.tryCatch, fn)
+	}
+}
+
+// optimizePeephole rewrites an op array with the peephole rules. Rules
+// replace the instructions they remove with NOPs, which are compacted
+// away last, relocating the op array's exception table.
+func optimizePeephole(instructions vm.Instructions, constants []interface{}, tryCatch []types.TryCatchElement) vm.Instructions {
+	for round := 0; round < maxPeepholeRounds; round++ {
+		changed := threadJumps(instructions)
+		changed = foldConstantConditions(instructions, constants) || changed
+		changed = removeJumpsToNext(instructions) || changed
+		changed = removeFreedAssignments(instructions, constants) || changed
+		if !changed {
+			break
+		}
+	}
+	return compactNops(instructions, constants, tryCatch)
+}
+
+// nop returns a NOP replacing an instruction of a line
+func nop(lineno uint32) vm.Instruction {
+	return vm.Instruction{Opcode: vm.OpNop, Lineno: lineno}
+}
+
+// threadJumps makes jumps landing on an unconditional JMP, possibly after
+// NOPs, jump to its target instead
+func threadJumps(instructions vm.Instructions) bool {
+	changed := false
+	for pos := range instructions {
+		target := jumpOperand(&instructions[pos])
+		if target == nil {
+			continue
+		}
+		dest := int(target.Value)
+		seen := map[int]bool{pos: true}
+		for dest < len(instructions) && !seen[dest] {
+			next := instructions[dest]
+			if next.Opcode == vm.OpNop {
+				dest++
+				continue
+			}
+			if next.Opcode != vm.OpJmp || !next.Op1.IsConst() {
+				break
+			}
+			// A loop of jumps ends at the first JMP seen twice
+			seen[dest] = true
+			dest = int(next.Op1.Value)
+		}
+		if dest != int(target.Value) {
+			target.Value = uint32(dest)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// foldConstantConditions turns a JMPZ or JMPNZ on a constant into a JMP if
+// it always jumps, or removes it if it never does
+func foldConstantConditions(instructions vm.Instructions, constants []interface{}) bool {
+	changed := false
+	for pos := range instructions {
+		instr := &instructions[pos]
+		if instr.Opcode != vm.OpJmpZ && instr.Opcode != vm.OpJmpNZ {
+			continue
+		}
+		if !instr.Op1.IsConst() || !instr.Op2.IsConst() {
+			continue
+		}
+		truth, ok := constantTruth(constants, int(instr.Op1.Value))
+		if !ok {
+			continue
+		}
+		if truth == (instr.Opcode == vm.OpJmpNZ) {
+			*instr = vm.Instruction{Opcode: vm.OpJmp, Op1: instr.Op2, Lineno: instr.Lineno}
+		} else {
+			*instr = nop(instr.Lineno)
+		}
+		changed = true
+	}
+	return changed
+}
+
+// constantTruth converts a scalar constant to bool as PHP does
+func constantTruth(constants []interface{}, index int) (truth bool, ok bool) {
+	if index >= len(constants) {
+		return false, false
+	}
+	switch v := constants[index].(type) {
+	case nil:
+		return false, true
+	case bool:
+		return v, true
+	case int64:
+		return v != 0, true
+	case float64:
+		return v != 0, true
+	case string:
+		return v != "" && v != "0", true
+	}
+	return false, false
+}
+
+// removeJumpsToNext removes unconditional jumps to the instruction that
+// follows them anyway
+func removeJumpsToNext(instructions vm.Instructions) bool {
+	changed := false
+	for pos := range instructions {
+		instr := &instructions[pos]
+		if instr.Opcode != vm.OpJmp || !instr.Op1.IsConst() {
+			continue
+		}
+		next := pos + 1
+		for next < len(instructions) && instructions[next].Opcode == vm.OpNop {
+			next++
+		}
+		if target := int(instr.Op1.Value); target > pos && target <= next {
+			*instr = nop(instr.Lineno)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// removeFreedAssignments removes a QM_ASSIGN of a constant to a temporary
+// together with the FREE of the temporary following it, the code of an
+// expression statement whose value is unused. A FREE that is a jump target
+// is kept, as other paths reach it.
+func removeFreedAssignments(instructions vm.Instructions, constants []interface{}) bool {
+	targets := jumpTargets(instructions, constants)
+	changed := false
+	for pos := 0; pos+1 < len(instructions); pos++ {
+		assign, free := &instructions[pos], &instructions[pos+1]
+		if assign.Opcode != vm.OpQMAssign || !assign.Op1.IsConst() || !assign.Result.IsTmpVar() {
+			continue
+		}
+		if free.Opcode != vm.OpFree || free.Op1 != assign.Result || targets[pos+1] {
+			continue
+		}
+		*assign = nop(assign.Lineno)
+		*free = nop(free.Lineno)
+		changed = true
+	}
+	return changed
+}
+
+// jumpTargets returns the positions jumps and jump tables may continue at
+func jumpTargets(instructions vm.Instructions, constants []interface{}) map[int]bool {
+	targets := make(map[int]bool)
+	for _, instr := range instructions {
+		if target, ok := jumpTarget(instr); ok {
+			targets[target] = true
+		}
+		if table := jumpTableOperand(constants, instr); table != nil {
+			for _, target := range table.Longs {
+				targets[target] = true
+			}
+			for _, target := range table.Strings {
+				targets[target] = true
+			}
+			targets[table.Default] = true
+		}
+	}
+	return targets
+}
+
+// compactNops removes the NOPs of an op array. Jumps to a removed NOP
+// continue at the instruction that followed it, and so do the regions of
+// the exception table starting there.
+func compactNops(instructions vm.Instructions, constants []interface{}, tryCatch []types.TryCatchElement) vm.Instructions {
+	moved := make([]int, len(instructions)+1)
+	kept := 0
+	for pos, instr := range instructions {
+		moved[pos] = kept
+		if instr.Opcode != vm.OpNop {
+			kept++
+		}
+	}
+	moved[len(instructions)] = kept
+	if kept == len(instructions) {
+		return instructions
+	}
+
+	relocate := func(target int) int {
+		if target < 0 || target >= len(moved) {
+			return target
+		}
+		return moved[target]
+	}
+	compacted := make(vm.Instructions, 0, kept)
+	relocated := make(map[*vm.JumpTable]bool)
+	for _, instr := range instructions {
+		if instr.Opcode == vm.OpNop {
+			continue
+		}
+		if target := jumpOperand(&instr); target != nil {
+			target.Value = uint32(relocate(int(target.Value)))
+		}
+		if table := jumpTableOperand(constants, instr); table != nil && !relocated[table] {
+			relocated[table] = true
+			for key, target := range table.Longs {
+				table.Longs[key] = relocate(target)
+			}
+			for key, target := range table.Strings {
+				table.Strings[key] = relocate(target)
+			}
+			table.Default = relocate(table.Default)
+		}
+		compacted = append(compacted, instr)
+	}
+	for i := range tryCatch {
+		tc := &tryCatch[i]
+		tc.TryOp = relocate(tc.TryOp)
+		if tc.CatchOp > 0 {
+			tc.CatchOp = relocate(tc.CatchOp)
+		}
+		if tc.FinallyOp > 0 {
+			tc.FinallyOp = relocate(tc.FinallyOp)
+			tc.FinallyEnd = relocate(tc.FinallyEnd)
+		}
+	}
+	return compacted
+}
+
+// ========================================
+// Tail Calls
+// ========================================
+
+// markSelfTailCalls marks the calls a function makes to itself by name
+// and returns the result of at once, which the VM runs in the frame
+// making them (see vm.CallTail). A call within a try statement is not
+// marked, as its exceptions must reach the handlers of the frame, nor are
+// the calls of functions returning by reference.
+func markSelfTailCalls(instructions vm.Instructions, constants []interface{}, tryCatch []types.TryCatchElement, fn *vm.CompiledFunction) bool {
+	if fn.ReturnByRef {
+		return false
+	}
+	changed := false
+	for pos := range instructions {
+		instr := &instructions[pos]
+		if instr.Opcode != vm.OpDoFcall || instr.ExtendedValue&vm.CallTail != 0 || !instr.Result.IsTmpVar() {
+			continue
+		}
+		if !returnsResult(instructions, pos) || withinTry(tryCatch, pos) {
+			continue
+		}
+		name, ok := calledFunction(instructions, constants, pos)
+		if !ok || !strings.EqualFold(strings.TrimPrefix(name, "\\"), fn.Name) {
+			continue
+		}
+		instr.ExtendedValue |= vm.CallTail
+		changed = true
+	}
+	return changed
+}
+
+// returnsResult reports whether the call at pos is followed by the return
+// of its result, possibly after checking it against the return type
+func returnsResult(instructions vm.Instructions, pos int) bool {
+	result := instructions[pos].Result
+	for next := pos + 1; next < len(instructions); next++ {
+		switch instr := instructions[next]; instr.Opcode {
+		case vm.OpNop:
+		case vm.OpVerifyReturnType:
+			if instr.Op1 != result || instr.Result != result {
+				return false
+			}
+		case vm.OpReturn:
+			return instr.Op1 == result
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// withinTry reports whether an instruction belongs to a try block, or to
+// a catch or finally block of a try statement with a finally block
+func withinTry(tryCatch []types.TryCatchElement, pos int) bool {
+	for _, tc := range tryCatch {
+		end := tc.CatchOp
+		if tc.FinallyOp > 0 {
+			end = tc.FinallyEnd + 1
+		}
+		if pos >= tc.TryOp && pos < end {
+			return true
+		}
+	}
+	return false
+}
+
+// calledFunction returns the name of the function the call at pos calls,
+// found at the INIT instruction starting it, past the calls made by its
+// arguments. Calls of methods and callable values have no name.
+func calledFunction(instructions vm.Instructions, constants []interface{}, pos int) (string, bool) {
+	nested := 0
+	for i := pos - 1; i >= 0; i-- {
+		instr := instructions[i]
+		var name vm.Operand
+		switch instr.Opcode {
+		case vm.OpDoFcall, vm.OpCallableConvert:
+			nested++
+			continue
+		case vm.OpInitFcall:
+			name = instr.Op2
+		case vm.OpInitFcallByName, vm.OpInitNsFcallByName:
+			name = instr.Op1
+		case vm.OpInitDynamicCall, vm.OpInitMethodCall, vm.OpInitStaticMethodCall, vm.OpInitUserCall:
+		default:
+			continue
+		}
+		if nested > 0 {
+			nested--
+			continue
+		}
+		if !name.IsConst() || int(name.Value) >= len(constants) {
+			return "", false
+		}
+		s, ok := constants[name.Value].(string)
+		return s, ok
+	}
+	return "", false
+}
@@ -0,0 +1,363 @@
+package compiler
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/lexer"
+	"github.com/krizos/php-go/pkg/parser"
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+func jmp(target uint32) vm.Instruction {
+	return vm.Instruction{Opcode: vm.OpJmp, Op1: vm.ConstOperand(target)}
+}
+
+func echo(constant uint32) vm.Instruction {
+	return vm.Instruction{Opcode: vm.OpEcho, Op1: vm.ConstOperand(constant)}
+}
+
+func TestPeephole_ThreadJumps(t *testing.T) {
+	instructions := vm.Instructions{
+		jmp(2),
+		{Opcode: vm.OpJmpZ, Op1: vm.CVOperand(0), Op2: vm.ConstOperand(3)},
+		jmp(4),
+		{Opcode: vm.OpNop},
+		jmp(5),
+		echo(0),
+	}
+
+	if !threadJumps(instructions) {
+		t.Fatal("Expected jumps to be threaded")
+	}
+	if instructions[0].Op1.Value != 5 {
+		t.Errorf("JMP should thread through two jumps to 5, got %d", instructions[0].Op1.Value)
+	}
+	if instructions[1].Op2.Value != 5 {
+		t.Errorf("JMPZ should thread past the NOP to 5, got %d", instructions[1].Op2.Value)
+	}
+	if threadJumps(instructions) {
+		t.Error("Threaded jumps should not change again")
+	}
+}
+
+func TestPeephole_ThreadJumpsLoop(t *testing.T) {
+	instructions := vm.Instructions{jmp(1), jmp(2), jmp(1)}
+	threadJumps(instructions)
+	for pos, instr := range instructions {
+		if target := instr.Op1.Value; target != 1 && target != 2 {
+			t.Errorf("Jump %d left the loop: %d", pos, target)
+		}
+	}
+}
+
+func TestPeephole_RemoveJumpsToNext(t *testing.T) {
+	instructions := vm.Instructions{
+		jmp(1),
+		jmp(4),
+		{Opcode: vm.OpNop},
+		{Opcode: vm.OpNop},
+		jmp(0),
+	}
+
+	if !removeJumpsToNext(instructions) {
+		t.Fatal("Expected jumps to be removed")
+	}
+	for pos, want := range []vm.Opcode{vm.OpNop, vm.OpNop, vm.OpNop, vm.OpNop, vm.OpJmp} {
+		if instructions[pos].Opcode != want {
+			t.Errorf("Instruction %d: expected %s, got %s", pos, want, instructions[pos].Opcode)
+		}
+	}
+}
+
+func TestPeephole_RemoveFreedAssignments(t *testing.T) {
+	instructions := vm.Instructions{
+		{Opcode: vm.OpQMAssign, Op1: vm.ConstOperand(0), Result: vm.TmpVarOperand(0)},
+		{Opcode: vm.OpFree, Op1: vm.TmpVarOperand(0)},
+		// The value of a variable is kept, as reading it may warn
+		{Opcode: vm.OpQMAssign, Op1: vm.CVOperand(0), Result: vm.TmpVarOperand(0)},
+		{Opcode: vm.OpFree, Op1: vm.TmpVarOperand(0)},
+		// A FREE of another temporary is kept
+		{Opcode: vm.OpQMAssign, Op1: vm.ConstOperand(0), Result: vm.TmpVarOperand(0)},
+		{Opcode: vm.OpFree, Op1: vm.TmpVarOperand(1)},
+	}
+
+	if !removeFreedAssignments(instructions, []interface{}{int64(5)}) {
+		t.Fatal("Expected the assignment to be removed")
+	}
+	want := []vm.Opcode{vm.OpNop, vm.OpNop, vm.OpQMAssign, vm.OpFree, vm.OpQMAssign, vm.OpFree}
+	for pos := range want {
+		if instructions[pos].Opcode != want[pos] {
+			t.Errorf("Instruction %d: expected %s, got %s", pos, want[pos], instructions[pos].Opcode)
+		}
+	}
+}
+
+func TestPeephole_RemoveFreedAssignmentsKeepsJumpTarget(t *testing.T) {
+	instructions := vm.Instructions{
+		jmp(2),
+		{Opcode: vm.OpQMAssign, Op1: vm.ConstOperand(0), Result: vm.TmpVarOperand(0)},
+		{Opcode: vm.OpFree, Op1: vm.TmpVarOperand(0)},
+	}
+
+	if removeFreedAssignments(instructions, []interface{}{int64(5)}) {
+		t.Error("A FREE other paths jump to should be kept")
+	}
+}
+
+func TestPeephole_FoldConstantConditions(t *testing.T) {
+	constants := []interface{}{int64(0), "yes", nil}
+	instructions := vm.Instructions{
+		{Opcode: vm.OpJmpZ, Op1: vm.ConstOperand(0), Op2: vm.ConstOperand(4), Lineno: 7},
+		{Opcode: vm.OpJmpNZ, Op1: vm.ConstOperand(0), Op2: vm.ConstOperand(4)},
+		{Opcode: vm.OpJmpNZ, Op1: vm.ConstOperand(1), Op2: vm.ConstOperand(4)},
+		{Opcode: vm.OpJmpZ, Op1: vm.CVOperand(0), Op2: vm.ConstOperand(4)},
+		// A condition patched over by its target is left alone
+		{Opcode: vm.OpJmpZ, Op1: vm.ConstOperand(2)},
+	}
+
+	if !foldConstantConditions(instructions, constants) {
+		t.Fatal("Expected conditions to be folded")
+	}
+	if instructions[0].Opcode != vm.OpJmp || instructions[0].Op1.Value != 4 || instructions[0].Lineno != 7 {
+		t.Errorf("JMPZ on false should become JMP 4, got %+v", instructions[0])
+	}
+	if instructions[1].Opcode != vm.OpNop {
+		t.Errorf("JMPNZ on false should be removed, got %s", instructions[1].Opcode)
+	}
+	if instructions[2].Opcode != vm.OpJmp || instructions[2].Op1.Value != 4 {
+		t.Errorf("JMPNZ on true should become JMP 4, got %+v", instructions[2])
+	}
+	if instructions[3].Opcode != vm.OpJmpZ || instructions[4].Opcode != vm.OpJmpZ {
+		t.Error("Variable and patched conditions should be kept")
+	}
+}
+
+func TestPeephole_CompactNops(t *testing.T) {
+	table := vm.NewJumpTable()
+	table.Strings["a"] = 3
+	table.Default = 6
+	constants := []interface{}{"x", table}
+	instructions := vm.Instructions{
+		{Opcode: vm.OpSwitchString, Op1: vm.CVOperand(0), Op2: vm.ConstOperand(1)},
+		{Opcode: vm.OpNop},
+		{Opcode: vm.OpJmpNZ, Op1: vm.CVOperand(0), Op2: vm.ConstOperand(5)},
+		{Opcode: vm.OpNop},
+		echo(0),
+		jmp(0),
+		{Opcode: vm.OpNop},
+	}
+
+	compacted := compactNops(instructions, constants, nil)
+	if len(compacted) != 4 {
+		t.Fatalf("Expected 4 instructions, got %d", len(compacted))
+	}
+	if target := compacted[1].Op2.Value; target != 3 {
+		t.Errorf("JMPNZ target should move from 5 to 3, got %d", target)
+	}
+	if target := compacted[3].Op1.Value; target != 0 {
+		t.Errorf("JMP target should stay 0, got %d", target)
+	}
+	// Targets on a NOP continue at the instruction that followed it
+	if table.Strings["a"] != 2 {
+		t.Errorf("Case \"a\" should move from 3 to 2, got %d", table.Strings["a"])
+	}
+	if table.Default != 4 {
+		t.Errorf("Default should move past the end to 4, got %d", table.Default)
+	}
+}
+
+// selfCall returns the instructions of "return fact($n - 1)" in fact()
+func selfCall() vm.Instructions {
+	return vm.Instructions{
+		{Opcode: vm.OpInitFcallByName, Op1: vm.ConstOperand(0), Op2: vm.ConstOperand(1)},
+		{Opcode: vm.OpSub, Op1: vm.CVOperand(0), Op2: vm.ConstOperand(2), Result: vm.TmpVarOperand(0)},
+		{Opcode: vm.OpSendVal, Op1: vm.TmpVarOperand(0)},
+		{Opcode: vm.OpDoFcall, Result: vm.TmpVarOperand(1)},
+		{Opcode: vm.OpReturn, Op1: vm.TmpVarOperand(1)},
+	}
+}
+
+func TestMarkSelfTailCalls(t *testing.T) {
+	fact := &vm.CompiledFunction{Name: "fact"}
+	constants := []interface{}{"Fact", int64(1), int64(1)}
+
+	instructions := selfCall()
+	if !markSelfTailCalls(instructions, constants, nil, fact) {
+		t.Fatal("Expected the self call to be marked")
+	}
+	if instructions[3].ExtendedValue&vm.CallTail == 0 {
+		t.Error("DO_FCALL should be marked as a tail call")
+	}
+	if markSelfTailCalls(instructions, constants, nil, fact) {
+		t.Error("A marked call should not change again")
+	}
+
+	// The call of another function, of a method or of a callable value
+	other := &vm.CompiledFunction{Name: "other"}
+	if markSelfTailCalls(selfCall(), constants, nil, other) {
+		t.Error("A call of another function should not be marked")
+	}
+	for _, init := range []vm.Opcode{vm.OpInitMethodCall, vm.OpInitDynamicCall} {
+		instructions := selfCall()
+		instructions[0].Opcode = init
+		if markSelfTailCalls(instructions, constants, nil, fact) {
+			t.Errorf("A call initialized by %s should not be marked", init)
+		}
+	}
+
+	// The return of another value
+	instructions = selfCall()
+	instructions[4].Op1 = vm.CVOperand(0)
+	if markSelfTailCalls(instructions, constants, nil, fact) {
+		t.Error("A call whose result is not returned should not be marked")
+	}
+
+	// A function returning by reference
+	byRef := &vm.CompiledFunction{Name: "fact", ReturnByRef: true}
+	if markSelfTailCalls(selfCall(), constants, nil, byRef) {
+		t.Error("A function returning by reference should not be marked")
+	}
+}
+
+func TestMarkSelfTailCallsNested(t *testing.T) {
+	// return fact(other(1)), with the return type checked
+	instructions := vm.Instructions{
+		{Opcode: vm.OpInitFcallByName, Op1: vm.ConstOperand(0), Op2: vm.ConstOperand(1)},
+		{Opcode: vm.OpInitFcallByName, Op1: vm.ConstOperand(1), Op2: vm.ConstOperand(2)},
+		{Opcode: vm.OpSendVal, Op1: vm.ConstOperand(2)},
+		{Opcode: vm.OpDoFcall, Result: vm.TmpVarOperand(0)},
+		{Opcode: vm.OpSendVal, Op1: vm.TmpVarOperand(0)},
+		{Opcode: vm.OpDoFcall, Result: vm.TmpVarOperand(1)},
+		{Opcode: vm.OpNop},
+		{Opcode: vm.OpVerifyReturnType, Op1: vm.TmpVarOperand(1), Result: vm.TmpVarOperand(1)},
+		{Opcode: vm.OpReturn, Op1: vm.TmpVarOperand(1)},
+	}
+	constants := []interface{}{"fact", "other", int64(1)}
+
+	if !markSelfTailCalls(instructions, constants, nil, &vm.CompiledFunction{Name: "fact"}) {
+		t.Fatal("Expected the outer call to be marked")
+	}
+	if instructions[3].ExtendedValue != 0 {
+		t.Error("The call of other() is not a tail call")
+	}
+	if instructions[5].ExtendedValue&vm.CallTail == 0 {
+		t.Error("The call of fact() should be marked as a tail call")
+	}
+
+	// other(fact(1)) calls fact() from an argument
+	instructions[0].Op1, instructions[1].Op1 = vm.ConstOperand(1), vm.ConstOperand(0)
+	instructions[3].ExtendedValue, instructions[5].ExtendedValue = 0, 0
+	if markSelfTailCalls(instructions, constants, nil, &vm.CompiledFunction{Name: "fact"}) {
+		t.Error("A call of another function should not be marked")
+	}
+}
+
+func TestMarkSelfTailCallsInTry(t *testing.T) {
+	fact := &vm.CompiledFunction{Name: "fact"}
+	constants := []interface{}{"fact", int64(1), int64(1)}
+
+	tests := []struct {
+		name     string
+		tryCatch types.TryCatchElement
+		marked   bool
+	}{
+		{"try block", types.TryCatchElement{TryOp: 0, CatchOp: 6}, false},
+		{"catch block", types.TryCatchElement{TryOp: 0, CatchOp: 2}, true},
+		{"catch block of try with finally", types.TryCatchElement{TryOp: 0, CatchOp: 2, FinallyOp: 6, FinallyEnd: 8}, false},
+		{"finally block", types.TryCatchElement{TryOp: 0, FinallyOp: 1, FinallyEnd: 5}, false},
+		{"after try", types.TryCatchElement{TryOp: 0, CatchOp: 1, FinallyOp: 1, FinallyEnd: 2}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marked := markSelfTailCalls(selfCall(), constants, []types.TryCatchElement{tt.tryCatch}, fact)
+			if marked != tt.marked {
+				t.Errorf("Expected marked=%v, got %v", tt.marked, marked)
+			}
+		})
+	}
+}
+
+func TestOptimizationLevels(t *testing.T) {
+	input := `<?php
+5;
+function f() { "unused"; return 1; }`
+
+	compile := func(level OptimizationLevel) *Bytecode {
+		p := parser.New(lexer.New(input, "test.php"))
+		program := p.ParseProgram()
+		c := New()
+		c.SetOptimizationLevel(level)
+		if err := c.Compile(program); err != nil {
+			t.Fatalf("Compilation failed: %v", err)
+		}
+		return c.Bytecode()
+	}
+
+	unoptimized := compile(OptimizeNone)
+	if _, ok := findOpcode(unoptimized.Instructions, vm.OpFree); !ok {
+		t.Error("-O0 should keep the FREE of the unused value")
+	}
+
+	optimized := compile(OptimizePeephole)
+	for _, instructions := range []vm.Instructions{optimized.Instructions, optimized.Functions[0].Instructions} {
+		for _, instr := range instructions {
+			if instr.Opcode == vm.OpFree || instr.Opcode == vm.OpNop {
+				t.Errorf("-O1 should remove the unused values, found %s", instr.Opcode)
+			}
+		}
+	}
+	if len(optimized.Instructions) >= len(unoptimized.Instructions) {
+		t.Errorf("Expected fewer instructions with -O1: %d, -O0: %d",
+			len(optimized.Instructions), len(unoptimized.Instructions))
+	}
+}
+
+func TestSelfTailCallsFromSource(t *testing.T) {
+	input := `<?php
+function fact($n, $acc) { if ($n < 2) { return $acc; } return fact($n - 1, $acc * $n); }
+function sum(int $n, int $acc): int { if ($n == 0) { return $acc; } return sum($n - 1, $acc + $n); }
+function depth($n) { if ($n == 0) { return 0; } return 1 + depth($n - 1); }
+echo fact(10, 1), " ", sum(5000, 0);`
+
+	c := New()
+	c.SetOptimizationLevel(OptimizePeephole)
+	if err := c.Compile(parser.New(lexer.New(input, "test.php")).ParseProgram()); err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	marked := map[string]bool{"fact": true, "sum": true, "depth": false}
+	for _, fn := range c.Bytecode().Functions {
+		want, ok := marked[fn.Name]
+		if !ok {
+			continue
+		}
+		call, found := findOpcode(fn.Instructions, vm.OpDoFcall)
+		if !found {
+			t.Fatalf("%s: no DO_FCALL", fn.Name)
+		}
+		if got := call.ExtendedValue&vm.CallTail != 0; got != want {
+			t.Errorf("%s: expected tail=%v, got %v", fn.Name, want, got)
+		}
+		delete(marked, fn.Name)
+	}
+	if len(marked) != 0 {
+		t.Fatalf("Functions not compiled: %v", marked)
+	}
+
+	// sum recurses deeper than the nesting limit, which only a tail call
+	// running in one frame stays within
+	script, err := ScriptCompiler(OptimizePeephole)("test.php", []byte(input))
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	var out bytes.Buffer
+	machine := vm.New()
+	machine.SetOutputWriter(&out)
+	if err := machine.ExecuteScript(script); err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	if got := out.String(); got != "3628800 12502500" {
+		t.Errorf("Expected %q, got %q", "3628800 12502500", got)
+	}
+}
@@ -0,0 +1,41 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/ast"
+)
+
+// ========================================
+// Readonly Properties
+// ========================================
+
+// checkReadonlyProperties reports readonly property declarations PHP
+// rejects at compile time. The properties of a readonly class are all
+// readonly, so they follow the same rules.
+func checkReadonlyProperties(node *ast.ClassDeclaration) error {
+	readonlyClass := false
+	for _, modifier := range node.Modifiers {
+		if modifier == "readonly" {
+			readonlyClass = true
+		}
+	}
+
+	for _, stmt := range node.Body {
+		decl, ok := stmt.(*ast.PropertyDeclaration)
+		if !ok || !(decl.Readonly || readonlyClass) {
+			continue
+		}
+		for _, prop := range decl.Properties {
+			switch {
+			case decl.Static:
+				return fmt.Errorf("static property %s::$%s cannot be readonly", node.Name.Value, prop.Name.Name)
+			case decl.Type == nil:
+				return fmt.Errorf("readonly property %s::$%s must have type", node.Name.Value, prop.Name.Name)
+			case prop.DefaultValue != nil:
+				return fmt.Errorf("readonly property %s::$%s cannot have default value", node.Name.Value, prop.Name.Name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// Return Statements and Return Types
+// ========================================
+
+// enterFunction starts compiling a function body whose returns are checked
+// against a declared return type; closures enter with none, so their
+// returns are not checked against the enclosing function's
+func (c *Compiler) enterFunction(returnType ast.Expr) {
+	decl := ""
+	if returnType != nil {
+		decl = returnType.String()
+	}
+	c.returnTypes = append(c.returnTypes, decl)
+}
+
+// exitFunction ends compiling a function body
+func (c *Compiler) exitFunction() {
+	if len(c.returnTypes) > 0 {
+		c.returnTypes = c.returnTypes[:len(c.returnTypes)-1]
+	}
+}
+
+// returnType returns the declared return type of the function being
+// compiled, lowercased for builtin types ("" outside functions and for
+// undeclared types)
+func (c *Compiler) returnType() string {
+	if len(c.returnTypes) == 0 {
+		return ""
+	}
+	return c.returnTypes[len(c.returnTypes)-1]
+}
+
+// compileReturn compiles a return statement. Returns from functions with
+// a declared type other than void are checked by VERIFY_RETURN_TYPE; as in
+// PHP, void functions cannot return a value, never functions cannot
+// return at all and other typed functions must return one. Returns leave
+// the enclosing try statements, running their finally blocks.
+func (c *Compiler) compileReturn(node *ast.ReturnStatement) error {
+	line := uint32(node.Token.Pos.Line)
+	decl := strings.ToLower(c.returnType())
+	switch {
+	case decl == "never":
+		return fmt.Errorf("a never-returning function must not return")
+	case decl == "void" && node.ReturnValue != nil:
+		return fmt.Errorf("a void function must not return a value")
+	case decl != "" && decl != "void" && node.ReturnValue == nil:
+		return fmt.Errorf("a function with return type must return a value")
+	}
+
+	if node.ReturnValue == nil {
+		c.leaveTries(len(c.tryStack), 0, line)
+		c.EmitWithLine(vm.OpReturn, line)
+		return nil
+	}
+	if err := c.Compile(node.ReturnValue); err != nil {
+		return err
+	}
+	if decl != "" {
+		c.EmitWithLine(vm.OpVerifyReturnType, line, vm.TmpVarOperand(0), vm.UnusedOperand(), vm.TmpVarOperand(0))
+	}
+	if c.hasFinally() {
+		// The finally blocks left run between the evaluation of the
+		// value and the return
+		result := c.keepValue(line)
+		c.leaveTries(len(c.tryStack), 0, line)
+		c.EmitWithLine(vm.OpReturn, line, result)
+		return nil
+	}
+	c.EmitWithLine(vm.OpReturn, line, vm.TmpVarOperand(0))
+	return nil
+}
+
+// emitImplicitReturn ends a function body that does not end with a
+// return. Falling off the end of a function with a declared return type
+// other than void is checked as returning no value.
+func (c *Compiler) emitImplicitReturn(line uint32) {
+	if c.LastInstructionIs(vm.OpReturn) || c.LastInstructionIs(vm.OpReturnByRef) {
+		return
+	}
+	if decl := c.returnType(); decl != "" && !strings.EqualFold(decl, "void") {
+		c.EmitWithLine(vm.OpVerifyReturnType, line, vm.UnusedOperand(), vm.UnusedOperand(), vm.UnusedOperand())
+	}
+	c.EmitWithLine(vm.OpReturn, line,
+		vm.UnusedOperand(),
+		vm.UnusedOperand(),
+		vm.UnusedOperand())
+}
@@ -0,0 +1,426 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// runSource compiles a script at every optimization level and runs it,
+// returning its output, which must not depend on the level
+func runSource(t *testing.T, source string) string {
+	t.Helper()
+
+	var outputs []string
+	for _, level := range []OptimizationLevel{OptimizeNone, OptimizePeephole, OptimizeDataFlow} {
+		script, err := ScriptCompiler(level)("test.php", []byte(source))
+		if err != nil {
+			t.Fatalf("Compilation failed: %v", err)
+		}
+
+		machine := vm.New()
+		if err := machine.ExecuteScript(script); err != nil {
+			t.Fatalf("Execution at level %d failed: %v\noutput: %s", level, err, machine.GetOutput())
+		}
+		outputs = append(outputs, machine.GetOutput())
+	}
+	for level, output := range outputs[1:] {
+		if output != outputs[0] {
+			t.Errorf("Output at level %d differs:\n%s\nunoptimized:\n%s", level+1, output, outputs[0])
+		}
+	}
+	return outputs[0]
+}
+
+// runTests runs scripts and checks their output
+func runTests(t *testing.T, tests []struct{ source, expected string }) {
+	t.Helper()
+	for _, tt := range tests {
+		if got := runSource(t, "<?php\n"+tt.source); got != tt.expected {
+			t.Errorf("%s\nexpected %q, got %q", tt.source, tt.expected, got)
+		}
+	}
+}
+
+func TestRun_Temporaries(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`$x = 3; echo $x;`, "3"},
+		{`if (false) { echo "a"; } else { echo "b"; }`, "b"},
+		{`if (0) { echo "a"; } elseif (1) { echo "b"; } else { echo "c"; }`, "b"},
+		{`function a($n) { return 7; } echo a(1);`, "7"},
+		{`function b($n) { if ($n > 3) { return $n; } return b($n + 1); } echo b(1);`, "4"},
+		{`function add($a, $b) { return $a + $b; } echo add(add(1, 2), add(3, 4));`, "10"},
+		{`$a = [1, 2, 3]; echo $a[0] + $a[2];`, "4"},
+		{`$m = ['a' => 1, 'b' => [2, 3]]; echo $m['b'][1];`, "3"},
+		{`$i = 2; echo ($i + 1) * ($i - 1) . "-" . -$i;`, "3--2"},
+		{`echo 2 > 1 ? "y" : "n", 0 ?: "alt", 5 ?: "alt";`, "yalt5"},
+		{`var_dump(1 || f(), 0 && f(), true xor true);`, "bool(true)\nbool(false)\nbool(false)\n"},
+		{`$s = 5; echo match(true) { $s > 3 => "big", default => "small" };`, "big"},
+		{`switch (2) { case 1: echo "one"; break; case 2: echo "two"; break; }`, "two"},
+		{`[$p, [$q, $r]] = [1, [2, 3]]; echo $p + $q + $r;`, "6"},
+		{`foreach (['a' => 1, 'b' => 2] as $k => $v) { echo $k, $v; }`, "a1b2"},
+		{`$a = [1, 2]; foreach ($a as $k => &$v) { $v *= 3; } unset($v); echo $a[0] + $a[1];`, "9"},
+		{`foreach ([[1, 2], [3, 4]] as [$x, $y]) { echo $x * $y; }`, "212"},
+		{`for ($i = 0, $j = 10; $i < 3; $i++) { echo $i; }`, "012"},
+		{`class P { public $v = 1; function __construct($v) { $this->v = $v; } function get() { return $this->v; } }
+$p = new P(5); $p->v = $p->get() + 1; echo $p->get(), $p instanceof P ? "y" : "n";`, "6y"},
+		{`class Q { static function make() { return new static(); } } echo Q::make()::class;`, "Q"},
+		{`class I { public $p = 1; function has() { return isset($this->p) ? "y" : "n"; } } echo (new I)->has();`, "y"},
+	})
+}
+
+func TestRun_ArrayContainers(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`$m = []; for ($i = 0; $i < 6; $i++) { $m[$i % 2] = ($m[$i % 2] ?? 0) + $i; } echo $m[0], " ", $m[1];`, "6 9"},
+		{`$a = [1, 2]; $b = $a; $b[0] = $a[1] + 5; echo $a[0], $b[0];`, "17"},
+		{`$g = ['x' => ['y' => 3]]; echo $g['x']['y'], isset($g['x']['z']) ? "set" : "unset", $g['x']['z'] ?? "-";`, "3unset-"},
+		{`function f() { $r = [5]; $s = $r; $s[0] += 1; return $r[0] . $s[0]; } echo f();`, "56"},
+	})
+}
+
+func TestRun_CompoundAssignment(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`$x = 3; $x += 2; echo $x;`, "5"},
+		{`$x = 5; $x -= 1; $x *= 3; $x /= 2; echo $x;`, "6"},
+		{`$s = "a"; $s .= "b"; echo $s;`, "ab"},
+		{`$y = 10; echo ($y += 5) * 2, $y;`, "3015"},
+		{`function f() { $t = 1; $t |= 6; $t ^= 2; $t &= 5; $t %= 3; $t **= 3; $t <<= 1; $t >>= 2; return $t; } echo f();`, "4"},
+		{`$a = [1, 2]; $a[1] += 5; echo $a[1];`, "7"},
+		{`$k = 'i'; $m = ['i' => 2]; $m[$k] <<= 3; echo $m['i'];`, "16"},
+		{`class C { public $n = 1; } $c = new C; $c->n += 4; $c->n **= 2; echo $c->n;`, "25"},
+		{`$z = null; $z ??= "set"; $z ??= "no"; echo $z;`, "set"},
+		{`$i = 5; echo $i++ + ++$i, " ", $i--, " ", --$i;`, "12 7 5"},
+	})
+}
+
+func TestRun_NestedElementAssignment(t *testing.T) {
+	declare := `class K { public $arr = ['k' => 1]; public $o; function f() { $this->arr['k'] += 1; $this->arr['j']['i'] = 2; return json_encode($this->arr); } }
+`
+	runTests(t, []struct{ source, expected string }{
+		{`$a = ['x' => ['y' => 1]]; $a['x']['y'] += 1; $a['x']['z'] = 5; echo json_encode($a);`, `{"x":{"y":2,"z":5}}`},
+		{`$a = []; $a['n']['m']['o'] = 1; echo json_encode($a);`, `{"n":{"m":{"o":1}}}`},
+		{`$b = [[1]]; $i = 0; $b[$i][$i] -= 5; echo json_encode($b);`, "[[-4]]"},
+		{`$a = ['x' => ['y' => 1]]; $b = $a; $b['x']['y'] = 2; echo json_encode($a), json_encode($b);`, `{"x":{"y":1}}{"x":{"y":2}}`},
+		{`for ($i = 0; $i < 2; $i++) { $a = ['x' => ['y' => 1]]; $a['x']['y'] += 10; echo $a['x']['y']; }`, "1111"},
+		{`$a = []; $a['n']['m'] = isset($a['n']); echo json_encode($a);`, `{"n":{"m":false}}`},
+		{declare + `$k = new K(); echo $k->f();`, `{"k":2,"j":{"i":2}}`},
+		{declare + `$k = new K(); $k->o = new K(); $k->o->arr['q'] = 3; $k->o->arr['q'] *= 2; echo json_encode($k->o->arr);`, `{"k":1,"q":6}`},
+		{declare + `$k = new K(); $k2 = new K(); $k->arr['k'] .= 'x'; echo $k->arr['k'], $k2->arr['k'];`, "1x1"},
+	})
+}
+
+func TestRun_InterpolatedStrings(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`$a = "b"; echo "x{$a}y";`, "xby"},
+		{`$a = "b"; echo "$a", "[$a$a]";`, "b[bb]"},
+		{`$n = 3; echo "n=$n, twice {$n}{$n}";`, "n=3, twice 33"},
+		{`$arr = ['k' => 'v', 2 => 'w']; $key = 'k'; echo "{$arr[$key]} $arr[k] $arr[2]";`, "v v w"},
+		{`class P { public $name = "p"; } $o = new P; echo "name: $o->name, {$o->name}";`, "name: p, p"},
+		{`$a = "out"; echo "a {$a} " . "b {$a}";`, "a out b out"},
+	})
+}
+
+func TestRun_ToString(t *testing.T) {
+	declare := `class S { function __toString() { return "S!"; } }
+class N {}
+`
+	runTests(t, []struct{ source, expected string }{
+		{declare + `echo new S();`, "S!"},
+		{declare + `$s = new S(); echo "x" . $s . "y";`, "xS!y"},
+		{declare + `$s = new S(); echo "in {$s} rope $s";`, "in S! rope S!"},
+		{declare + `$t = "pre"; $t .= new S(); echo $t;`, "preS!"},
+		{declare + `try { echo new N(); } catch (Error $e) { echo $e->getMessage(); }`, "Object of class N could not be converted to string"},
+		{declare + `try { echo "a" . new N(); } catch (Error $e) { echo $e->getMessage(); }`, "Object of class N could not be converted to string"},
+	})
+}
+
+func TestRun_IntegerOverflow(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`echo PHP_INT_MAX + 1;`, "9.2233720368548E+18"},
+		{`$max = PHP_INT_MAX; echo $max + 1, " ", $max * 2;`, "9.2233720368548E+18 1.844674407371E+19"},
+		{`$min = PHP_INT_MIN; echo $min - 1, " ", -$min, " ", gettype(-$min);`, "-9.2233720368548E+18 9.2233720368548E+18 double"},
+		{`$i = PHP_INT_MAX; $i++; echo $i, " ", gettype($i);`, "9.2233720368548E+18 double"},
+		{`$i = PHP_INT_MAX; $i++; echo gettype($i), " ", gettype(PHP_INT_MAX * 2), " ", gettype(PHP_INT_MIN - 1);`, "double double double"},
+		{`var_dump(PHP_INT_MAX + 1 == 2 ** 63);`, "bool(true)\n"},
+	})
+}
+
+func TestRun_Exceptions(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`try { throw new Exception(); } catch (Exception $e) { echo "[", $e->getMessage(), "]"; }`, "[]"},
+		{`try { throw new Exception("boom", 3); } catch (Exception $e) { echo $e->getMessage(), $e->getCode(); }`, "boom3"},
+		{`$e = new Exception("prev"); $f = new LogicException("outer", 0, $e); echo $f->getPrevious()->getMessage();`, "prev"},
+		{`try { throw new RuntimeException("rt"); } catch (LogicException $e) { echo "logic"; } catch (RuntimeException $e) { echo $e::class; }`, "RuntimeException"},
+		{`class MyException extends Exception { function __construct($m) { parent::__construct("my: " . $m); } }
+try { throw new MyException("x"); } catch (Exception $e) { echo $e->getMessage(); }`, "my: x"},
+		{`function thrower() { throw new InvalidArgumentException("bad"); }
+try { thrower(); } catch (LogicException $e) { echo $e::class, " ", $e->getMessage(); }`, "InvalidArgumentException bad"},
+	})
+}
+
+func TestRun_TryCatchFinally(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`try { throw new Exception(); } catch (Exception $e) { echo "caught"; }`, "caught"},
+		{`try { echo "a"; } catch (Exception $e) { echo "b"; } finally { echo "c"; }`, "ac"},
+		{`try { throw new Exception("x"); } catch (TypeError | Exception $e) { echo "multi"; }`, "multi"},
+		{`try { try { throw new Exception("inner"); } finally { echo "f "; } } catch (Exception $e) { echo $e->getMessage(); }`, "f inner"},
+		{`try { try { throw new Exception("a"); } catch (Exception $e) { throw new RuntimeException("b", 0, $e); } }
+catch (RuntimeException $e) { echo $e->getMessage(), "<-", $e->getPrevious()->getMessage(); }`, "b<-a"},
+		{`function f($x) {
+    try {
+        if ($x) { throw new Exception("e$x"); }
+        return "ret";
+    } catch (Exception $e) {
+        return "catch " . $e->getMessage();
+    } finally {
+        echo "finally$x ";
+    }
+}
+echo f(0), " ", f(1);`, "finally0 ret finally1 catch e1"},
+		{`for ($i = 0; $i < 3; $i++) { try { if ($i == 1) { continue; } echo $i; } finally { echo "f"; } }`, "0ff2f"},
+		{`try { intdiv(1, 0); } catch (DivisionByZeroError $e) { echo $e::class; }`, "DivisionByZeroError"},
+	})
+}
+
+func TestRun_InheritedMethods(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`abstract class Base { public function describe(): string { return "I am " . static::class; } }
+class S extends Base {} echo (new S)->describe();`, "I am S"},
+		{`class A { function twice($n) { return $n * 2; } } class B extends A {} class C extends B {} echo (new C)->twice(21);`, "42"},
+		{`trait Greets { public function hi() { return "hi from " . self::class; } } class G { use Greets; } echo (new G)->hi();`, "hi from G"},
+	})
+}
+
+func TestRun_PropertyVisibility(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`class A { private $q = 5; function get() { return $this->q; } } echo (new A)->get();`, "5"},
+		{`class B { private float $side; function __construct(float $side) { $this->side = $side; } function area() { return $this->side * $this->side; } }
+echo (new B(3))->area();`, "9"},
+		{`class C { protected $p; function set($v) { $this->p = $v; return isset($this->p) ? "set" : "unset"; } } echo (new C)->set(1);`, "set"},
+	})
+}
+
+func TestRun_FunctionScope(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`$a = [[2]]; $b = [[3]]; $i = 0; $x = 0;
+function f($a, $b) { $i = 0; $x = 0; $x += $a[$i][0] * $b[0][$i]; return $x; }
+echo f($a, $b), $i, $x;`, "600"},
+		{`$x = 5; $y = 1; function g() { global $x; $y = 2; return $x + $y; } echo g(), $y;`, "71"},
+		{`$v = "outer"; function h() { return isset($v) ? "set" : "unset"; } echo h();`, "unset"},
+		{`$n = 10; function c() { static $n = 0; return ++$n; } c(); echo c(), $n;`, "210"},
+		{`$e = 1; function k() { try { throw new Exception("x"); } catch (Exception $e) { return $e->getMessage(); } } echo k(), $e;`, "x1"},
+	})
+}
+
+func TestRun_ReferenceArguments(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`function r(&$p) { $p = 5; } r($x); echo $x;`, "5"},
+		{`function r(&$p) { $p = 5; } r(p: $x); echo $x;`, "5"},
+		{`function byval($p) { $p = 9; } $q = 1; byval($q); echo $q;`, "1"},
+		{`function inc(int &$i) { $i++; } $s = "7"; inc($s); var_dump($s);`, "int(8)\n"},
+		{`class C { function m(&$x) { $x = "m"; } } (new C)->m($z); echo $z;`, "m"},
+		{`function r(&$p) { $p = "dyn"; } $f = "r"; $f($d); echo $d;`, "dyn"},
+		{`$a = [3, 1, 2]; sort($a); echo $a[0], $a[1], $a[2];`, "123"},
+		{`$a = [3, 1, 2]; usort($a, fn($x, $y) => $y <=> $x); echo $a[0], $a[1], $a[2];`, "321"},
+		{`$n = ["k" => [3, 2, 1]]; sort($n["k"]); echo $n["k"][0], $n["k"][2];`, "13"},
+		{`$w = [1, 2, 3]; array_walk($w, function (&$v, $k) { $v = $v * 10; }); echo $w[0], $w[1], $w[2];`, "102030"},
+		{`preg_match('/(\d+)/', "ab12", $m); echo $m[1];`, "12"},
+		{`$v = "123abc"; settype($v, "int"); var_dump($v);`, "int(123)\n"},
+		{`sscanf("age: 42 name: bob", "age: %d name: %s", $age, $name); echo $age, $name;`, "42bob"},
+	})
+}
+
+func TestRun_VariadicNamedArguments(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`function f(...$a) { echo json_encode($a); } f(1, x: 2);`, `{"0":1,"x":2}`},
+		{`function g($a, $b = 5, ...$rest) { echo $a, $b, json_encode($rest); } g(1, c: 3, b: 2);`, `12{"c":3}`},
+		{`function f(...$a) { echo json_encode($a); } call_user_func_array('f', [1, 'k' => 'v']);`, `{"0":1,"k":"v"}`},
+		{`$h = function (...$xs) { echo $xs["y"]; }; $h(y: 4);`, "4"},
+		{`function h($a) {} $n = "h"; try { $n(1, z: 2); } catch (Error $e) { echo $e->getMessage(); }`, "Unknown named parameter $z"},
+	})
+}
+
+func TestRun_PregConstants(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`echo json_encode(preg_split('/(,)/', 'a,,b', -1, PREG_SPLIT_NO_EMPTY | PREG_SPLIT_DELIM_CAPTURE));`, `["a",",",",","b"]`},
+		{`preg_match('/b/', 'ab', $m, PREG_OFFSET_CAPTURE); echo json_encode($m);`, `[["b",1]]`},
+		{`preg_match_all('/\d/', 'a1b2', $m, PREG_SET_ORDER); echo json_encode($m), preg_last_error() === PREG_NO_ERROR ? "ok" : "";`, `[["1"],["2"]]ok`},
+	})
+}
+
+func TestRun_JsonConstants(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`echo json_encode(['a/b' => 1.0], JSON_UNESCAPED_SLASHES | JSON_PRESERVE_ZERO_FRACTION);`, `{"a/b":1.0}`},
+		{`echo json_encode([1], JSON_PRETTY_PRINT);`, "[\n    1\n]"},
+		{`json_decode('{'); var_dump(json_last_error() === JSON_ERROR_SYNTAX);`, "bool(true)\n"},
+		{`try { json_decode('{', false, 512, JSON_THROW_ON_ERROR); } catch (JsonException $e) { echo $e->getMessage(); }`, "Syntax error"},
+	})
+}
+
+func TestRun_MbCaseConstants(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`echo mb_convert_case("hello wörld", MB_CASE_TITLE), mb_convert_case("Ä", MB_CASE_LOWER);`, "Hello Wörldä"},
+		{`echo mb_convert_case("straße", MB_CASE_UPPER), mb_convert_case("straße", MB_CASE_UPPER_SIMPLE);`, "STRASSESTRAßE"},
+	})
+}
+
+func TestRun_MemoryStreams(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`$f = fopen('php://memory', 'w+'); fwrite($f, "ab\ncd"); rewind($f); echo fgets($f), ftell($f);`, "ab\n3"},
+		{`$f = fopen('php://temp', 'w+'); fwrite($f, "hello"); fseek($f, -3, SEEK_END); echo fread($f, 10), fseek($f, 1, SEEK_CUR);`, "llo-1"},
+		{`echo "a"; fwrite(fopen('php://stdout', 'w'), "b"); echo "c";`, "abc"},
+		{`var_dump(@fopen('php://bogus', 'r'));`, "bool(false)\n"},
+		{`echo "a"; fwrite(STDOUT, "b"); fprintf(STDOUT, "%03d", 7); echo "c", get_resource_type(STDERR);`, "ab007cstream"},
+	})
+}
+
+func TestRun_BuiltinArgumentTypes(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`class S { function __toString(): string { return "abc"; } } echo strlen(new S), strlen(5), @strlen(null), round("1.5");`, "3102"},
+		{`try { strlen([]); } catch (TypeError $e) { echo $e->getMessage(); }`, "strlen(): Argument #1 ($string) must be of type string, array given"},
+		{"declare(strict_types=1);\ntry { strlen(5); } catch (TypeError $e) { echo $e->getMessage(); }", "strlen(): Argument #1 ($string) must be of type string, int given"},
+		{"declare(strict_types=1);\ntry { round(\"1.5\"); } catch (TypeError $e) { echo $e->getMessage(); }", "round(): Argument #1 ($num) must be of type int|float, string given"},
+		{"declare(strict_types=1);\n$lengths = array_map('strlen', ['a', 'bc']);\necho strlen(string: \"abc\"), round(2.5), $lengths[0], $lengths[1];", "3312"},
+	})
+}
+
+func TestRun_ListTargets(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`$a = [0, 0, ['k' => 0]]; [$a[0], $a[2]['k']] = [1, 2]; echo $a[0], $a[1], $a[2]['k'];`, "102"},
+		{`class P { public $x; public $y; } $p = new P; list(, $p->x, $p->y) = [9, 'a', 'b']; echo $p->x, $p->y;`, "ab"},
+		{`class P { public static $s; } ['u' => P::$s] = ['u' => 'S']; $n = 'd'; [$$n] = ['D']; echo P::$s, $d;`, "SD"},
+		{`$i = 0; $t = [0, 0]; [$t[$i++], $t[$i++]] = ['x', 'y']; echo $t[0], $t[1], $i;`, "xy2"},
+		{`class P { public $y; } $p = new P; $a = [0]; foreach ([[1, 2], [3, 4]] as [$a[0], $p->y]) { echo $a[0], $p->y; }`, "1234"},
+		{`$arr = [1, 2, 3]; foreach ($arr as &$v) { $v = $v * 2; } unset($v); echo $arr[0], $arr[1], $arr[2];`, "246"},
+	})
+}
+
+func TestRun_DateTimeEdgeCases(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`$d = new DateTimeImmutable('2021-03-14 01:30:00', new DateTimeZone('America/New_York')); echo $d->add(new DateInterval('PT1H'))->format('H:i T'), " ", $d->format('H:i T');`, "03:30 EDT 01:30 EST"},
+		{`$d = new DateTimeImmutable('2021-03-13 02:30:00', new DateTimeZone('America/New_York')); echo $d->add(new DateInterval('P1D'))->format('Y-m-d H:i P');`, "2021-03-14 03:30 -04:00"},
+		{`$d = new DateTime('@0'); echo $d->getTimezone()->getName(), " ", $d->format(DATE_ATOM);`, "+00:00 1970-01-01T00:00:00+00:00"},
+		{`var_dump(DATE_ATOM === DateTimeInterface::ATOM, DATE_RFC2822 === DateTime::RFC2822);`, "bool(true)\nbool(true)\n"},
+	})
+}
+
+func TestRun_TailCallTraces(t *testing.T) {
+	// Self tail calls run in one frame at -O1 and -O2, which runSource
+	// checks gives the traces of the unoptimized calls
+	runTests(t, []struct{ source, expected string }{
+		{"function f($n) {\n  if ($n == 0) { throw new Exception('x'); }\n  return f($n - 1);\n}\n" +
+			"function g() { return f(2); }\ntry { g(); } catch (Exception $e) { echo $e->getTraceAsString(); }",
+			"#0 test.php(4): f()\n#1 test.php(4): f()\n#2 test.php(6): f()\n#3 test.php(7): g()\n#4 {main}"},
+		{"function h($n) {\n  if ($n == 0) { throw new Exception('x'); }\n  if ($n % 2) {\n    return h($n - 1);\n  }\n  return h($n - 1);\n}\n" +
+			"try { h(3); } catch (Exception $e) { echo $e->getTraceAsString(); }",
+			"#0 test.php(5): h()\n#1 test.php(7): h()\n#2 test.php(5): h()\n#3 test.php(9): h()\n#4 {main}"},
+	})
+}
+
+func TestRun_ClosureBindings(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`$x = 1; $f = function () use ($x) { return $x; }; $x = 2; echo $f(), $x;`, "12"},
+		{`$x = 1; $f = function () use ($x) { $x++; return $x; }; echo $f(), $f(), $x;`, "221"},
+		{`$c = 0; $inc = function () use (&$c) { $c++; }; $inc(); $inc(); echo $c;`, "2"},
+		{`function counter() { $n = 0; return function () use (&$n) { return ++$n; }; } $k = counter(); $k(); echo $k();`, "2"},
+		{`$x = 3; $g = fn() => $x * 10; $x = 4; echo $g();`, "30"},
+		{`$y = 5; $add = fn($a) => fn($b) => $a + $b + $y; echo $add(1)(2);`, "8"},
+		{`$y = 2; $m = array_map(fn($i) => $i * $y, [1, 2]); echo $m[0], $m[1];`, "24"},
+		{`class K { private $v = "k"; } $x = 1; $peek = function () use ($x) { return $this->v . $x; };
+echo Closure::bind($peek, new K, K::class)();`, "k1"},
+		{`$n = 1; $f = function () use (&$n) { return $n; }; $b = Closure::bind($f, null, null); $n = 7; echo $b();`, "7"},
+	})
+}
+
+func TestRun_MixedTypeConstants(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`var_dump(1 + "1.5");`, "float(2.5)\n"},
+		{`var_dump(1 + true);`, "int(2)\n"},
+		{`var_dump(@(2 * "3x"));`, "int(6)\n"},
+		{`var_dump(0 == "a");`, "bool(false)\n"},
+		{`var_dump(1.5 == "1.5");`, "bool(true)\n"},
+		{`var_dump(3 < "10");`, "bool(true)\n"},
+		{`var_dump(null == 0.0, 1.5 + 1);`, "bool(true)\nfloat(2.5)\n"},
+	})
+}
+
+func TestRun_InterfaceConstants(t *testing.T) {
+	runTests(t, []struct{ source, expected string }{
+		{`interface I { const V = "1.0"; public const MAX = 10; const TWICE = self::MAX * 2; } echo I::V, I::MAX, I::TWICE;`, "1.01020"},
+		{`interface I { const A = "a"; } interface J extends I { const B = "b"; }
+class C implements J { function f() { return self::A . static::B; } } echo C::A, J::A, (new C)->f();`, "aaab"},
+		{`interface I { const A = 1; } class C implements I {} var_dump(defined("C::A"));`, "bool(true)\n"},
+	})
+}
+
+func TestRun_DestructorTiming(t *testing.T) {
+	declare := `class D { public $n; function __construct($n) { $this->n = $n; } function __destruct() { echo "~", $this->n, " "; } }
+class H { public $d; }
+`
+	runTests(t, []struct{ source, expected string }{
+		{declare + `$a = new D(1); $a = null; echo "null ";`, "~1 null "},
+		{declare + `$b = new D(2); unset($b); echo "unset ";`, "~2 unset "},
+		{declare + `$c = new D(3); $c = new D(4); echo "overwrite ";`, "~3 overwrite ~4 "},
+		{declare + `new D(5); echo "temp ";`, "~5 temp "},
+		{declare + `$e = new D(6); $f = $e; $e = null; echo "shared "; $f = 1; echo "last ";`, "shared ~6 last "},
+		{declare + `$h = new H(); $h->d = new D(7); $h->d = null; echo "property ";`, "~7 property "},
+		{declare + `function g() { $x = new D(8); $x = null; echo "local "; $y = new D(9); echo "return "; } g(); echo "after ";`,
+			"~8 local return ~9 after "},
+	})
+}
+
+func TestRun_UnsetThis(t *testing.T) {
+	declare := `class A {
+	public $p = 1;
+	public $d = ['a' => 1, 'b' => 2];
+	function drop() { unset($this->p); return isset($this->p) ? "set" : "unset"; }
+	function dropKey($k) { unset($this->d[$k]); return json_encode($this->d); }
+	function magic() { unset($this->missing); }
+	function __unset($name) { echo "__unset($name)"; }
+}
+`
+	runTests(t, []struct{ source, expected string }{
+		{declare + `$a = new A(); echo $a->drop();`, "unset"},
+		{declare + `$a = new A(); echo $a->dropKey('a');`, `{"b":2}`},
+		{declare + `$a = new A(); $a->magic();`, "__unset(missing)"},
+		{`$a = ['x' => ['p' => 1, 'q' => 2]]; $k = 'p'; unset($a['x'][$k]); echo json_encode($a);`, `{"x":{"q":2}}`},
+	})
+}
+
+func TestRun_ParentPrivateProperties(t *testing.T) {
+	declare := `class B {
+	private $pri = 'b';
+	public $pub = 'p';
+	function getB() { return $this->pri; }
+	function setB($v) { $this->pri = $v; }
+	function varsB() { return get_object_vars($this); }
+}
+class C extends B { private $pri = 'c'; function getC() { return $this->pri; } }
+class D extends B { function hasD() { return isset($this->pri) ? "set" : "unset"; } }
+trait T { private $t = 't'; function getT() { return $this->t; } }
+class U { use T; }
+class V extends U {}
+`
+	runTests(t, []struct{ source, expected string }{
+		{declare + `$c = new C(); echo $c->getB(), $c->getC();`, "bc"},
+		{declare + `$c = new C(); $c->setB('x'); echo $c->getB(), $c->getC();`, "xc"},
+		{declare + `$c = new C(); echo json_encode($c->varsB());`, `{"pri":"b","pub":"p"}`},
+		{declare + `$d = new D(); echo $d->getB(), $d->hasD();`, "bunset"},
+		{declare + `$v = new V(); echo $v->getT();`, "t"},
+		{declare + `print_r(new C());`, "C Object\n(\n    [pri:B:private] => b\n    [pub] => p\n    [pri:C:private] => c\n)\n"},
+	})
+}
+
+func TestRun_PropertyOrder(t *testing.T) {
+	declare := `class P { public $z = 1; public $a = 2; }
+class Q extends P { public $m = 3; public $b = 4; public $z = 5; }
+`
+	runTests(t, []struct{ source, expected string }{
+		{declare + `$q = new Q(); $q->dyn2 = 6; $q->dyn1 = 7; echo json_encode($q);`, `{"z":5,"a":2,"m":3,"b":4,"dyn2":6,"dyn1":7}`},
+		{declare + `$q = new Q(); $q->d = 6; echo json_encode(get_object_vars($q));`, `{"z":5,"a":2,"m":3,"b":4,"d":6}`},
+		{declare + `$q = new Q(); foreach ($q as $k => $v) { echo $k; }`, "zamb"},
+		{declare + `$q = new Q(); print_r($q);`, "Q Object\n(\n    [z] => 5\n    [a] => 2\n    [m] => 3\n    [b] => 4\n)\n"},
+		{declare + `$q = new Q(); $q->x = 1; $q->y = 2; unset($q->x, $q->a); $q->x = 3; $q->a = 4; echo json_encode($q);`, `{"z":5,"a":4,"m":3,"b":4,"y":2,"x":3}`},
+		{`echo json_encode(json_decode('{"y":1,"b":2,"a":3}'));`, `{"y":1,"b":2,"a":3}`},
+	})
+}
@@ -0,0 +1,841 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// SSA Form
+// ========================================
+
+// The data-flow passes (-O2) work on an SSA form of an op array: its
+// control flow graph of basic blocks, with every write of a temporary or a
+// compiled variable defining a new value and phis merging the values
+// reaching a block from its predecessors. The values carry the types they
+// may have, inferred over a lattice of PHP types. The form is built from
+// the instructions for each round of passes and is not kept.
+//
+// Compiled variables are only tracked while nothing can reach them but
+// the instructions reading and assigning them: a variable bound by
+// reference, passed to a function that may take it by reference or
+// modified in place is not, and no variable is in the main op array (its
+// variables are the globals), in closures (their bound variables may be
+// references) or in op arrays that access variables by name (include,
+// compact(), variable variables). Op arrays with exception handling are
+// left alone, as their control flow graph misses the edges to the
+// handlers.
+//
+// Temporaries are stored in the locals of the frame after the compiled
+// variables, so the slots of temporaries and compiled variables are
+// distinct; the form numbers temporaries below zero.
+
+// typeMask is a set of the types a value may have
+type typeMask uint16
+
+const (
+	typeUndef typeMask = 1 << iota
+	typeNull
+	typeFalse
+	typeTrue
+	typeLong
+	typeDouble
+	typeString
+	typeArray
+	typeObject
+	typeResource
+
+	typeBool   = typeFalse | typeTrue
+	typeNumber = typeLong | typeDouble
+	typeScalar = typeNull | typeBool | typeNumber | typeString
+	typeAny    = typeUndef | typeScalar | typeArray | typeObject | typeResource
+)
+
+// typeNames names the types of a mask, in bit order
+var typeNames = []string{"undef", "null", "false", "true", "long", "double", "string", "array", "object", "resource"}
+
+// String lists the types of a mask as "long|double"
+func (t typeMask) String() string {
+	if t == typeAny {
+		return "any"
+	}
+	var names []string
+	for i, name := range typeNames {
+		if t&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// within reports whether all the types of a mask are in another
+func (t typeMask) within(other typeMask) bool {
+	return t != 0 && t&^other == 0
+}
+
+// constantType returns the type of a constant table entry
+func constantType(value interface{}) typeMask {
+	switch v := value.(type) {
+	case nil:
+		return typeNull
+	case bool:
+		if v {
+			return typeTrue
+		}
+		return typeFalse
+	case int64:
+		return typeLong
+	case float64:
+		return typeDouble
+	case string:
+		return typeString
+	}
+	return typeAny
+}
+
+// declaredType returns the types a declared parameter type admits, the
+// types of the value received after coercion
+func declaredType(decl string) typeMask {
+	if decl == "" {
+		return typeAny
+	}
+	var mask typeMask
+	if strings.HasPrefix(decl, "?") {
+		mask = typeNull
+		decl = decl[1:]
+	}
+	for _, alternative := range strings.Split(decl, "|") {
+		switch strings.ToLower(strings.TrimSpace(alternative)) {
+		case "int":
+			mask |= typeLong
+		case "float":
+			mask |= typeDouble
+		case "string":
+			mask |= typeString
+		case "bool":
+			mask |= typeBool
+		case "false":
+			mask |= typeFalse
+		case "true":
+			mask |= typeTrue
+		case "null":
+			mask |= typeNull
+		case "array":
+			mask |= typeArray
+		case "object":
+			mask |= typeObject
+		default:
+			// mixed, iterable, callable and intersections admit anything
+			return typeAny
+		}
+	}
+	return mask
+}
+
+// ssaVariable is a compiled variable, numbered by its slot, or a
+// temporary, numbered -1, -2, ...
+type ssaVariable int
+
+// ssaValue is one definition of a variable
+type ssaValue struct {
+	variable ssaVariable
+	def      int     // Defining instruction, -1 for a phi or the entry value
+	phi      *ssaPhi // Defining phi
+	typ      typeMask
+}
+
+// ssaPhi merges the values of a variable reaching a block
+type ssaPhi struct {
+	block  int
+	args   []*ssaValue // One per predecessor
+	result *ssaValue
+}
+
+// ssaBlock is a basic block: instructions [start, end) entered only at
+// start and left only at end-1
+type ssaBlock struct {
+	start, end int
+	succs      []int
+	preds      []int
+	idom       int   // Immediate dominator, -1 for the entry and unreachable blocks
+	frontier   []int // Dominance frontier
+	children   []int // Blocks immediately dominated
+	phis       map[ssaVariable]*ssaPhi
+	reachable  bool
+}
+
+// ssaForm is the SSA form of an op array
+type ssaForm struct {
+	instructions vm.Instructions
+	constants    []interface{}
+	params       []*types.ParameterDef
+
+	blocks  []*ssaBlock
+	blockOf []int // Block of each instruction
+	tracked map[ssaVariable]bool
+	values  []*ssaValue
+
+	// Per instruction: the values read by Op1 and Op2, the value a Result
+	// modified in place reads, and the value Result defines
+	op1, op2, prior, result []*ssaValue
+}
+
+// Opcodes ending a block with no successor
+var terminators = map[vm.Opcode]bool{
+	vm.OpReturn:          true,
+	vm.OpReturnByRef:     true,
+	vm.OpThrow:           true,
+	vm.OpExit:            true,
+	vm.OpMatchError:      true,
+	vm.OpGeneratorReturn: true,
+}
+
+// Opcodes that only read their compiled variable operands, leaving the
+// variables as they were
+var variableReaders = map[vm.Opcode]bool{
+	vm.OpQMAssign: true, vm.OpAssign: true, vm.OpEcho: true, vm.OpFree: true,
+	vm.OpAdd: true, vm.OpSub: true, vm.OpMul: true, vm.OpDiv: true, vm.OpMod: true, vm.OpPow: true,
+	vm.OpSL: true, vm.OpSR: true, vm.OpBWOr: true, vm.OpBWAnd: true, vm.OpBWXor: true, vm.OpBWNot: true,
+	vm.OpConcat: true, vm.OpFastConcat: true, vm.OpBoolNot: true, vm.OpBoolXor: true, vm.OpBool: true,
+	vm.OpIsIdentical: true, vm.OpIsNotIdentical: true, vm.OpIsEqual: true, vm.OpIsNotEqual: true,
+	vm.OpIsSmaller: true, vm.OpIsSmallerOrEqual: true, vm.OpSpaceship: true, vm.OpCast: true,
+	vm.OpJmpZ: true, vm.OpJmpNZ: true, vm.OpJmpZEx: true, vm.OpJmpNZEx: true,
+	vm.OpReturn: true, vm.OpSendVal: true, vm.OpFetchR: true, vm.OpFetchDimR: true,
+	vm.OpFetchObjR: true, vm.OpFetchDimIs: true, vm.OpFetchObjIs: true, vm.OpIssetIsemptyCV: true,
+	vm.OpStrlen: true, vm.OpCount: true, vm.OpTypeCheck: true, vm.OpInstanceof: true,
+	vm.OpRopeInit: true, vm.OpRopeAdd: true, vm.OpRopeEnd: true, vm.OpSwitchLong: true,
+	vm.OpSwitchString: true, vm.OpMatch: true, vm.OpCase: true, vm.OpCaseStrict: true,
+	vm.OpGetType: true, vm.OpCoalesce: true, vm.OpJmpSet: true, vm.OpJmpNull: true,
+}
+
+// Opcodes that may write their Op1 or Op2 operands
+var operandWriters = map[vm.Opcode]bool{
+	vm.OpAssignDim: true, vm.OpAssignObj: true, vm.OpAssignOp: true, vm.OpAssignDimOp: true,
+	vm.OpAssignObjOp: true, vm.OpAssignRef: true, vm.OpPreInc: true, vm.OpPreDec: true,
+	vm.OpPostInc: true, vm.OpPostDec: true, vm.OpFetchDimW: true, vm.OpFetchObjW: true,
+	vm.OpFetchDimRW: true, vm.OpFetchObjRW: true, vm.OpFetchDimFuncArg: true,
+	vm.OpFetchObjFuncArg: true, vm.OpFetchDimUnset: true, vm.OpFetchObjUnset: true,
+	vm.OpFetchListW: true, vm.OpUnsetCV: true, vm.OpBindGlobal: true,
+}
+
+// Opcodes defining their Result without reading it
+var resultDefiners = map[vm.Opcode]bool{
+	vm.OpQMAssign: true, vm.OpAssign: true, vm.OpRecv: true, vm.OpRecvInit: true, vm.OpRecvVariadic: true,
+	vm.OpAdd: true, vm.OpSub: true, vm.OpMul: true, vm.OpDiv: true, vm.OpMod: true, vm.OpPow: true,
+	vm.OpSL: true, vm.OpSR: true, vm.OpBWOr: true, vm.OpBWAnd: true, vm.OpBWXor: true, vm.OpBWNot: true,
+	vm.OpConcat: true, vm.OpFastConcat: true, vm.OpBoolNot: true, vm.OpBoolXor: true, vm.OpBool: true,
+	vm.OpIsIdentical: true, vm.OpIsNotIdentical: true, vm.OpIsEqual: true, vm.OpIsNotEqual: true,
+	vm.OpIsSmaller: true, vm.OpIsSmallerOrEqual: true, vm.OpSpaceship: true, vm.OpCast: true,
+	vm.OpFetchR: true, vm.OpFetchDimR: true, vm.OpFetchObjR: true, vm.OpStrlen: true,
+	vm.OpCount: true, vm.OpTypeCheck: true, vm.OpInstanceof: true, vm.OpInitArray: true,
+	vm.OpRopeInit: true, vm.OpRopeEnd: true, vm.OpGetType: true,
+}
+
+// Opcodes whose control flow the graph does not model
+var exceptionOpcodes = map[vm.Opcode]bool{
+	vm.OpCatch:            true,
+	vm.OpFastCall:         true,
+	vm.OpFastRet:          true,
+	vm.OpDiscardException: true,
+	vm.OpHandleException:  true,
+}
+
+// Opcodes that may access compiled variables by name
+var namedAccessOpcodes = map[vm.Opcode]bool{
+	vm.OpIncludeOrEval:         true,
+	vm.OpUnsetVar:              true,
+	vm.OpIssetIsemptyVar:       true,
+	vm.OpDeclareLambdaFunction: true, // Arrow functions capture by name
+}
+
+// Functions reading or writing the variables of their caller by name
+var namedAccessFunctions = map[string]bool{
+	"compact":          true,
+	"extract":          true,
+	"get_defined_vars": true,
+	"parse_str":        true,
+	"mb_parse_str":     true,
+}
+
+// buildSSA builds the SSA form of an op array, or returns nil if the op
+// array cannot be analyzed. Without fn it is the main op array.
+func buildSSA(instructions vm.Instructions, constants []interface{}, fn *vm.CompiledFunction) *ssaForm {
+	for _, instr := range instructions {
+		if exceptionOpcodes[instr.Opcode] {
+			return nil
+		}
+	}
+	s := &ssaForm{
+		instructions: instructions,
+		constants:    constants,
+		op1:          make([]*ssaValue, len(instructions)),
+		op2:          make([]*ssaValue, len(instructions)),
+		prior:        make([]*ssaValue, len(instructions)),
+		result:       make([]*ssaValue, len(instructions)),
+	}
+	if fn != nil {
+		s.params = fn.Parameters
+	}
+	s.trackVariables(fn)
+	s.buildBlocks()
+	if len(s.blocks) > 0 && len(s.blocks[0].preds) > 0 {
+		// A jump back to the first instruction would need a phi merging
+		// the entry values
+		return nil
+	}
+	s.computeDominators()
+	s.placePhis()
+	s.rename()
+	s.inferTypes()
+	return s
+}
+
+// trackVariables selects the variables the form tracks: the temporaries
+// no instruction modifies in place, and the compiled variables only the
+// instructions can reach
+func (s *ssaForm) trackVariables(fn *vm.CompiledFunction) {
+	s.tracked = make(map[ssaVariable]bool)
+	trackCVs := fn != nil && fn.Name != "{closure}"
+	for _, value := range s.constants {
+		if name, ok := value.(string); ok {
+			if i := strings.LastIndex(name, "\\"); i >= 0 {
+				name = name[i+1:]
+			}
+			if namedAccessFunctions[strings.ToLower(name)] {
+				trackCVs = false
+			}
+		}
+	}
+
+	untracked := make(map[ssaVariable]bool)
+	compiled := make(map[ssaVariable]bool)
+	for _, instr := range s.instructions {
+		if namedAccessOpcodes[instr.Opcode] {
+			trackCVs = false
+		}
+		switch instr.Opcode {
+		case vm.OpFetchR, vm.OpFetchW, vm.OpFetchRW, vm.OpFetchIs, vm.OpFetchUnset, vm.OpFetchFuncArg:
+			// A variable variable names its variable at runtime
+			if !instr.Op1.IsCV() || instr.ExtendedValue&vm.FetchByName != 0 {
+				trackCVs = false
+			}
+		case vm.OpAssign, vm.OpAssignRef:
+			if instr.ExtendedValue&vm.FetchByName != 0 {
+				trackCVs = false
+			}
+		case vm.OpFeFetchR, vm.OpFeFetchRW:
+			// The key is stored in the temporary after the value
+			untracked[ssaVariable(-2-int(instr.Result.Value))] = true
+		}
+		operands := []vm.Operand{instr.Op1, instr.Op2, instr.Result}
+		for i, op := range operands {
+			variable, ok := s.operandVariable(op)
+			if !ok {
+				continue
+			}
+			s.tracked[variable] = true
+			if op.IsTmpVar() {
+				if i < 2 && operandWriters[instr.Opcode] {
+					untracked[variable] = true
+				}
+				continue
+			}
+			compiled[variable] = true
+			reads := i < 2 && variableReaders[instr.Opcode]
+			defines := i == 2 && (instr.Opcode == vm.OpAssign || instr.Opcode == vm.OpQMAssign || s.receivesByValue(instr))
+			if !reads && !defines {
+				untracked[variable] = true
+			}
+		}
+	}
+	for variable := range s.tracked {
+		s.tracked[variable] = !untracked[variable] && (trackCVs || !compiled[variable])
+	}
+}
+
+// operandVariable returns the variable a temporary or compiled variable
+// operand names
+func (s *ssaForm) operandVariable(op vm.Operand) (ssaVariable, bool) {
+	switch {
+	case op.IsCV(), op.IsVar():
+		return ssaVariable(op.Value), true
+	case op.IsTmpVar():
+		return ssaVariable(-1 - int(op.Value)), true
+	}
+	return 0, false
+}
+
+// receivesByValue reports whether an instruction receives a parameter
+// passed by value
+func (s *ssaForm) receivesByValue(instr vm.Instruction) bool {
+	switch instr.Opcode {
+	case vm.OpRecv, vm.OpRecvInit, vm.OpRecvVariadic:
+		index := int(instr.Op1.Value)
+		return index < len(s.params) && !s.params[index].PassedByRef
+	}
+	return false
+}
+
+// definedVariable returns the tracked variable an instruction writes: its
+// Result, or the temporary a FREE clears
+func (s *ssaForm) definedVariable(pos int) (ssaVariable, bool) {
+	instr := s.instructions[pos]
+	if instr.Opcode == vm.OpFree {
+		if !instr.Op1.IsTmpVar() {
+			return 0, false
+		}
+		return s.isTracked(instr.Op1)
+	}
+	return s.isTracked(instr.Result)
+}
+
+// isTracked reports whether the form tracks the variable an operand names
+func (s *ssaForm) isTracked(op vm.Operand) (ssaVariable, bool) {
+	variable, ok := s.operandVariable(op)
+	return variable, ok && s.tracked[variable]
+}
+
+// successors returns the instructions control may continue at after an
+// instruction, len(instructions) for the end of the op array
+func (s *ssaForm) successors(pos int) []int {
+	instr := s.instructions[pos]
+	if terminators[instr.Opcode] {
+		return nil
+	}
+	var succs []int
+	if target, ok := jumpTarget(instr); ok {
+		succs = append(succs, target)
+		if instr.Opcode == vm.OpJmp {
+			return succs
+		}
+	}
+	if table := jumpTableOperand(s.constants, instr); table != nil {
+		for _, target := range table.Longs {
+			succs = append(succs, target)
+		}
+		for _, target := range table.Strings {
+			succs = append(succs, target)
+		}
+		succs = append(succs, table.Default)
+	}
+	return append(succs, pos+1)
+}
+
+// buildBlocks splits the instructions into basic blocks and links them
+func (s *ssaForm) buildBlocks() {
+	n := len(s.instructions)
+	leaders := make([]bool, n+1)
+	leaders[0] = true
+	for pos := range s.instructions {
+		succs := s.successors(pos)
+		if len(succs) != 1 || succs[0] != pos+1 {
+			leaders[pos+1] = true
+			for _, target := range succs {
+				if target >= 0 && target <= n {
+					leaders[target] = true
+				}
+			}
+		}
+	}
+
+	s.blockOf = make([]int, n)
+	for pos := 0; pos < n; pos++ {
+		if leaders[pos] {
+			s.blocks = append(s.blocks, &ssaBlock{start: pos, idom: -1})
+		}
+		block := len(s.blocks) - 1
+		s.blockOf[pos] = block
+		s.blocks[block].end = pos + 1
+	}
+
+	for i, block := range s.blocks {
+		seen := make(map[int]bool)
+		for _, target := range s.successors(block.end - 1) {
+			if target < 0 || target >= n || seen[target] {
+				continue
+			}
+			seen[target] = true
+			succ := s.blockOf[target]
+			block.succs = append(block.succs, succ)
+			s.blocks[succ].preds = append(s.blocks[succ].preds, i)
+		}
+	}
+}
+
+// computeDominators finds the immediate dominators and dominance
+// frontiers of the reachable blocks, with the iterative algorithm of
+// Cooper, Harvey and Kennedy
+func (s *ssaForm) computeDominators() {
+	if len(s.blocks) == 0 {
+		return
+	}
+
+	// Reverse postorder of the reachable blocks
+	var postorder []int
+	var visit func(int)
+	visit = func(b int) {
+		s.blocks[b].reachable = true
+		for _, succ := range s.blocks[b].succs {
+			if !s.blocks[succ].reachable {
+				visit(succ)
+			}
+		}
+		postorder = append(postorder, b)
+	}
+	visit(0)
+	order := make([]int, len(s.blocks))
+	for i, b := range postorder {
+		order[b] = i
+	}
+
+	idom := make([]int, len(s.blocks))
+	for i := range idom {
+		idom[i] = -1
+	}
+	idom[0] = 0
+	intersect := func(a, b int) int {
+		for a != b {
+			for order[a] < order[b] {
+				a = idom[a]
+			}
+			for order[b] < order[a] {
+				b = idom[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(postorder) - 2; i >= 0; i-- {
+			b := postorder[i]
+			newIdom := -1
+			for _, pred := range s.blocks[b].preds {
+				if idom[pred] == -1 {
+					continue
+				}
+				if newIdom == -1 {
+					newIdom = pred
+				} else {
+					newIdom = intersect(pred, newIdom)
+				}
+			}
+			if idom[b] != newIdom {
+				idom[b] = newIdom
+				changed = true
+			}
+		}
+	}
+
+	for b, block := range s.blocks {
+		if b == 0 || !block.reachable {
+			continue
+		}
+		block.idom = idom[b]
+		s.blocks[idom[b]].children = append(s.blocks[idom[b]].children, b)
+	}
+
+	// A block is in the frontier of the blocks dominating a predecessor
+	// but not the block itself
+	for b, block := range s.blocks {
+		if !block.reachable || len(block.preds) < 2 {
+			continue
+		}
+		for _, pred := range block.preds {
+			if !s.blocks[pred].reachable {
+				continue
+			}
+			for runner := pred; runner != idom[b]; runner = idom[runner] {
+				s.blocks[runner].frontier = appendUnique(s.blocks[runner].frontier, b)
+			}
+		}
+	}
+}
+
+// appendUnique appends a block to a list unless it is already in it
+func appendUnique(list []int, b int) []int {
+	for _, existing := range list {
+		if existing == b {
+			return list
+		}
+	}
+	return append(list, b)
+}
+
+// placePhis inserts the phis of each tracked variable at the iterated
+// dominance frontier of the blocks defining it
+func (s *ssaForm) placePhis() {
+	defSites := make(map[ssaVariable][]int)
+	for b, block := range s.blocks {
+		if !block.reachable {
+			continue
+		}
+		block.phis = make(map[ssaVariable]*ssaPhi)
+		for pos := block.start; pos < block.end; pos++ {
+			if variable, ok := s.definedVariable(pos); ok {
+				defSites[variable] = appendUnique(defSites[variable], b)
+			}
+		}
+	}
+
+	for variable, sites := range defSites {
+		work := append([]int(nil), sites...)
+		for len(work) > 0 {
+			b := work[len(work)-1]
+			work = work[:len(work)-1]
+			for _, f := range s.blocks[b].frontier {
+				if _, ok := s.blocks[f].phis[variable]; ok {
+					continue
+				}
+				phi := &ssaPhi{block: f, args: make([]*ssaValue, len(s.blocks[f].preds))}
+				phi.result = s.newValue(variable, -1)
+				phi.result.phi = phi
+				s.blocks[f].phis[variable] = phi
+				work = append(work, f)
+			}
+		}
+	}
+}
+
+// newValue creates a value of a variable
+func (s *ssaForm) newValue(variable ssaVariable, def int) *ssaValue {
+	value := &ssaValue{variable: variable, def: def}
+	s.values = append(s.values, value)
+	return value
+}
+
+// rename links every read of a tracked variable to the value reaching it,
+// walking the dominator tree with a stack of values per variable
+func (s *ssaForm) rename() {
+	if len(s.blocks) == 0 {
+		return
+	}
+	stacks := make(map[ssaVariable][]*ssaValue)
+	entries := make(map[ssaVariable]*ssaValue)
+	current := func(variable ssaVariable) *ssaValue {
+		if stack := stacks[variable]; len(stack) > 0 {
+			return stack[len(stack)-1]
+		}
+		// The value on entry: set by the caller, or undefined
+		if entries[variable] == nil {
+			entries[variable] = s.newValue(variable, -1)
+		}
+		return entries[variable]
+	}
+	read := func(op vm.Operand) *ssaValue {
+		if variable, ok := s.isTracked(op); ok {
+			return current(variable)
+		}
+		return nil
+	}
+
+	var walk func(int)
+	walk = func(b int) {
+		block := s.blocks[b]
+		pushed := make(map[ssaVariable]int)
+		define := func(value *ssaValue) {
+			stacks[value.variable] = append(stacks[value.variable], value)
+			pushed[value.variable]++
+		}
+
+		for _, phi := range block.phis {
+			define(phi.result)
+		}
+		for pos := block.start; pos < block.end; pos++ {
+			instr := s.instructions[pos]
+			s.op1[pos] = read(instr.Op1)
+			s.op2[pos] = read(instr.Op2)
+			if variable, ok := s.definedVariable(pos); ok {
+				if !resultDefiners[instr.Opcode] {
+					s.prior[pos] = current(variable)
+				}
+				value := s.newValue(variable, pos)
+				s.result[pos] = value
+				define(value)
+			}
+		}
+		for _, succ := range block.succs {
+			for i, pred := range s.blocks[succ].preds {
+				if pred != b {
+					continue
+				}
+				for variable, phi := range s.blocks[succ].phis {
+					phi.args[i] = current(variable)
+				}
+			}
+		}
+		for _, child := range block.children {
+			walk(child)
+		}
+		for variable, n := range pushed {
+			stacks[variable] = stacks[variable][:len(stacks[variable])-n]
+		}
+	}
+	walk(0)
+}
+
+// inferTypes computes the types of the values, starting from the types of
+// the entry values and growing them until they hold for every path
+func (s *ssaForm) inferTypes() {
+	for _, value := range s.values {
+		if value.def < 0 && value.phi == nil {
+			value.typ = typeAny
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		widen := func(value *ssaValue, typ typeMask) {
+			if value.typ|typ != value.typ {
+				value.typ |= typ
+				changed = true
+			}
+		}
+		for _, block := range s.blocks {
+			if !block.reachable {
+				continue
+			}
+			for _, phi := range block.phis {
+				for _, arg := range phi.args {
+					if arg != nil {
+						widen(phi.result, arg.typ)
+					}
+				}
+			}
+			for pos := block.start; pos < block.end; pos++ {
+				if value := s.result[pos]; value != nil {
+					widen(value, s.resultType(pos))
+				}
+			}
+		}
+	}
+}
+
+// operandType returns the types an operand of an instruction may have
+func (s *ssaForm) operandType(op vm.Operand, value *ssaValue) typeMask {
+	switch {
+	case value != nil:
+		return value.typ
+	case op.IsConst():
+		if int(op.Value) < len(s.constants) {
+			return constantType(s.constants[op.Value])
+		}
+	case op.IsUnused():
+		return typeNull
+	}
+	return typeAny
+}
+
+// op1Type and op2Type return the types of the operands of an instruction
+func (s *ssaForm) op1Type(pos int) typeMask {
+	return s.operandType(s.instructions[pos].Op1, s.op1[pos])
+}
+
+func (s *ssaForm) op2Type(pos int) typeMask {
+	return s.operandType(s.instructions[pos].Op2, s.op2[pos])
+}
+
+// castTypes are the types of the CAST targets, by ExtendedValue
+var castTypes = map[uint32]typeMask{
+	1: typeLong,
+	2: typeBool,
+	3: typeDouble,
+	4: typeString,
+	5: typeArray,
+	6: typeObject,
+	7: typeNull,
+}
+
+// resultType returns the types of the value an instruction defines
+func (s *ssaForm) resultType(pos int) typeMask {
+	instr := s.instructions[pos]
+	switch instr.Opcode {
+	case vm.OpQMAssign, vm.OpFetchR:
+		return copiedType(s.op1Type(pos))
+	case vm.OpAssign:
+		return copiedType(s.op2Type(pos))
+	case vm.OpRecv, vm.OpRecvInit, vm.OpRecvVariadic:
+		return s.receivedType(pos)
+	case vm.OpAdd, vm.OpSub, vm.OpMul:
+		return arithmeticType(instr.Opcode, s.op1Type(pos), s.op2Type(pos))
+	case vm.OpDiv, vm.OpPow:
+		return typeNumber
+	case vm.OpMod, vm.OpSL, vm.OpSR, vm.OpSpaceship, vm.OpStrlen, vm.OpCount:
+		return typeLong
+	case vm.OpBWAnd, vm.OpBWOr, vm.OpBWXor:
+		if s.op1Type(pos).within(typeString) && s.op2Type(pos).within(typeString) {
+			return typeString
+		}
+		if s.op1Type(pos)&typeString == 0 || s.op2Type(pos)&typeString == 0 {
+			return typeLong
+		}
+		return typeLong | typeString
+	case vm.OpConcat, vm.OpFastConcat, vm.OpRopeInit, vm.OpRopeAdd, vm.OpRopeEnd, vm.OpGetType:
+		return typeString
+	case vm.OpIsIdentical, vm.OpIsNotIdentical, vm.OpIsEqual, vm.OpIsNotEqual,
+		vm.OpIsSmaller, vm.OpIsSmallerOrEqual, vm.OpBool, vm.OpBoolNot, vm.OpBoolXor,
+		vm.OpInstanceof, vm.OpTypeCheck, vm.OpIssetIsemptyCV:
+		return typeBool
+	case vm.OpCast:
+		if typ, ok := castTypes[instr.ExtendedValue]; ok {
+			return typ
+		}
+	case vm.OpInitArray, vm.OpAddArrayElement:
+		return typeArray
+	case vm.OpFree:
+		// The slot is cleared unless it is a compiled variable's too
+		return typeNull | s.prior[pos].typ
+	}
+	return typeAny
+}
+
+// copiedType returns the types of a copy of a value; reading an undefined
+// variable gives null
+func copiedType(typ typeMask) typeMask {
+	if typ&typeUndef != 0 {
+		typ = typ&^typeUndef | typeNull
+	}
+	return typ
+}
+
+// arithmeticType returns the types of the result of ADD, SUB or MUL.
+// Integer results that overflow become floats.
+func arithmeticType(op vm.Opcode, left, right typeMask) typeMask {
+	integral := typeUndef | typeNull | typeBool | typeLong
+	var typ typeMask
+	switch {
+	case left.within(integral) && right.within(integral):
+		typ = typeNumber
+	case (left == typeDouble && right.within(integral|typeDouble)) ||
+		(right == typeDouble && left.within(integral|typeDouble)):
+		typ = typeDouble
+	default:
+		typ = typeNumber
+	}
+	if op == vm.OpAdd && left&typeArray != 0 && right&typeArray != 0 {
+		typ |= typeArray // Array union
+	}
+	return typ
+}
+
+// receivedType returns the types of a received parameter: those its
+// declaration admits, or the default value's
+func (s *ssaForm) receivedType(pos int) typeMask {
+	instr := s.instructions[pos]
+	index := int(instr.Op1.Value)
+	if instr.Opcode == vm.OpRecvVariadic {
+		return typeArray
+	}
+	if index >= len(s.params) {
+		return typeAny
+	}
+	param := s.params[index]
+	typ := declaredType(param.Type)
+	if param.HasDefault && param.Default != nil && param.Default.IsNull() {
+		typ |= typeNull // T $x = null is implicitly nullable
+	}
+	if instr.Opcode == vm.OpRecvInit {
+		typ |= copiedType(s.op2Type(pos))
+	}
+	return typ
+}
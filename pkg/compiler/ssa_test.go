@@ -0,0 +1,240 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/lexer"
+	"github.com/krizos/php-go/pkg/parser"
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+func tmp(index uint32) vm.Operand {
+	return vm.TmpVarOperand(index)
+}
+
+func TestTypeMask(t *testing.T) {
+	tests := []struct {
+		decl string
+		want typeMask
+	}{
+		{"int", typeLong},
+		{"?string", typeNull | typeString},
+		{"int|float", typeNumber},
+		{"bool", typeBool},
+		{"mixed", typeAny},
+		{"Countable", typeAny},
+		{"", typeAny},
+	}
+	for _, tt := range tests {
+		if got := declaredType(tt.decl); got != tt.want {
+			t.Errorf("declaredType(%q) = %s, want %s", tt.decl, got, tt.want)
+		}
+	}
+
+	if got := (typeNull | typeNumber).String(); got != "null|long|double" {
+		t.Errorf("Expected \"null|long|double\", got %q", got)
+	}
+	if !typeLong.within(typeNumber) || typeNumber.within(typeLong) || typeMask(0).within(typeAny) {
+		t.Error("within() gave a wrong answer")
+	}
+}
+
+func TestSSA_PhiJoinsTypes(t *testing.T) {
+	constants := []interface{}{int64(1), 1.5}
+	instructions := vm.Instructions{
+		{Opcode: vm.OpQMAssign, Op1: vm.ConstOperand(0), Result: tmp(0)},
+		{Opcode: vm.OpJmpZ, Op1: tmp(3), Op2: vm.ConstOperand(3)},
+		{Opcode: vm.OpQMAssign, Op1: vm.ConstOperand(1), Result: tmp(0)},
+		{Opcode: vm.OpAdd, Op1: tmp(0), Op2: tmp(0), Result: tmp(1)},
+		{Opcode: vm.OpEcho, Op1: tmp(1)},
+	}
+
+	s := buildSSA(instructions, constants, nil)
+	if s == nil {
+		t.Fatal("Expected an SSA form")
+	}
+	if len(s.blocks) != 3 {
+		t.Fatalf("Expected 3 blocks, got %d", len(s.blocks))
+	}
+	joined := s.op1[3]
+	if joined == nil || joined.phi == nil {
+		t.Fatal("ADD should read the phi merging both assignments")
+	}
+	if joined.typ != typeNumber {
+		t.Errorf("Phi type: expected long|double, got %s", joined.typ)
+	}
+	if got := s.result[3].typ; got != typeNumber {
+		t.Errorf("ADD type: expected long|double, got %s", got)
+	}
+	if got := s.op1Type(1); got != typeAny {
+		t.Errorf("A value set before the op array should be any, got %s", got)
+	}
+}
+
+func TestSSA_Bailout(t *testing.T) {
+	withCatch := vm.Instructions{
+		{Opcode: vm.OpCatch, Op1: vm.ConstOperand(0), Result: vm.CVOperand(0)},
+		echo(0),
+	}
+	if buildSSA(withCatch, []interface{}{"Exception"}, nil) != nil {
+		t.Error("An op array with exception handlers should not be analyzed")
+	}
+
+	loopToStart := vm.Instructions{echo(0), jmp(0)}
+	if buildSSA(loopToStart, []interface{}{"x"}, nil) != nil {
+		t.Error("An op array jumping back to its first instruction should not be analyzed")
+	}
+}
+
+func TestDataFlow_RemoveRedundantConversions(t *testing.T) {
+	constants := []interface{}{int64(5)}
+	instructions := vm.Instructions{
+		{Opcode: vm.OpQMAssign, Op1: vm.ConstOperand(0), Result: tmp(0)},
+		{Opcode: vm.OpCast, ExtendedValue: 1, Op1: tmp(0), Result: tmp(1)},
+		{Opcode: vm.OpCast, ExtendedValue: 4, Op1: tmp(0), Result: tmp(2)},
+		{Opcode: vm.OpBool, Op1: tmp(9), Result: tmp(3)},
+		{Opcode: vm.OpIsEqual, Op1: tmp(0), Op2: tmp(0), Result: tmp(4)},
+		{Opcode: vm.OpBool, Op1: tmp(4), Result: tmp(5)},
+	}
+	for _, index := range []uint32{1, 2, 3, 5} {
+		instructions = append(instructions, vm.Instruction{Opcode: vm.OpEcho, Op1: tmp(index)})
+	}
+
+	s := buildSSA(instructions, constants, nil)
+	if !s.removeRedundantConversions() {
+		t.Fatal("Expected conversions to be removed")
+	}
+	want := []vm.Opcode{vm.OpQMAssign, vm.OpQMAssign, vm.OpCast, vm.OpBool, vm.OpIsEqual, vm.OpQMAssign}
+	for pos := range want {
+		if instructions[pos].Opcode != want[pos] {
+			t.Errorf("Instruction %d: expected %s, got %s", pos, want[pos], instructions[pos].Opcode)
+		}
+	}
+	if instructions[1].Op1 != tmp(0) || instructions[1].Result != tmp(1) || instructions[1].ExtendedValue != 0 {
+		t.Errorf("The copy should keep the operands of the cast, got %+v", instructions[1])
+	}
+}
+
+func TestDataFlow_SpecializeOperations(t *testing.T) {
+	constants := []interface{}{int64(2), 0.5, "a"}
+	instructions := vm.Instructions{
+		{Opcode: vm.OpQMAssign, Op1: vm.ConstOperand(0), Result: tmp(0)},
+		{Opcode: vm.OpQMAssign, Op1: vm.ConstOperand(1), Result: tmp(1)},
+		{Opcode: vm.OpAdd, Op1: tmp(0), Op2: tmp(0), Result: tmp(2)},
+		{Opcode: vm.OpMul, Op1: tmp(0), Op2: tmp(1), Result: tmp(3)},
+		{Opcode: vm.OpSub, Op1: tmp(0), Op2: tmp(9), Result: tmp(4)},
+		{Opcode: vm.OpConcat, Op1: vm.ConstOperand(2), Op2: vm.ConstOperand(2), Result: tmp(5)},
+		{Opcode: vm.OpConcat, Op1: vm.ConstOperand(2), Op2: tmp(0), Result: tmp(6)},
+	}
+	for index := uint32(2); index <= 6; index++ {
+		instructions = append(instructions, vm.Instruction{Opcode: vm.OpEcho, Op1: tmp(index)})
+	}
+
+	s := buildSSA(instructions, constants, nil)
+	if !s.specializeOperations() {
+		t.Fatal("Expected operations to be specialized")
+	}
+	if got := instructions[2].ExtendedValue; got != vm.ArithLong {
+		t.Errorf("int + int: expected ArithLong, got %d", got)
+	}
+	if got := instructions[3].ExtendedValue; got != vm.ArithDouble {
+		t.Errorf("int * float: expected ArithDouble, got %d", got)
+	}
+	if got := instructions[4].ExtendedValue; got != 0 {
+		t.Errorf("An operand of unknown type should not be specialized, got %d", got)
+	}
+	if instructions[5].Opcode != vm.OpFastConcat {
+		t.Errorf("Concatenating strings should use FAST_CONCAT, got %s", instructions[5].Opcode)
+	}
+	if instructions[6].Opcode != vm.OpConcat {
+		t.Errorf("Concatenating an int should keep CONCAT, got %s", instructions[6].Opcode)
+	}
+	if s.specializeOperations() {
+		t.Error("Specialized operations should not change again")
+	}
+}
+
+func TestDataFlow_RemoveDeadStores(t *testing.T) {
+	constants := []interface{}{int64(1), int64(2), "unused"}
+	instructions := vm.Instructions{
+		{Opcode: vm.OpQMAssign, Op1: vm.ConstOperand(0), Result: tmp(0)},
+		{Opcode: vm.OpQMAssign, Op1: vm.ConstOperand(1), Result: tmp(0)},
+		{Opcode: vm.OpAdd, Op1: tmp(0), Op2: tmp(0), Result: tmp(1)},
+		{Opcode: vm.OpQMAssign, Op1: vm.ConstOperand(1), Result: tmp(2)},
+		{Opcode: vm.OpEcho, Op1: tmp(2)},
+		{Opcode: vm.OpQMAssign, Op1: vm.ConstOperand(2), Result: tmp(3)},
+		{Opcode: vm.OpFree, Op1: tmp(3)},
+		// A slot that may hold an object keeps its stores
+		{Opcode: vm.OpQMAssign, Op1: tmp(9), Result: tmp(4)},
+		// So does a call
+		{Opcode: vm.OpDoFcall, Result: tmp(5)},
+	}
+
+	optimized := optimizeDataFlow(instructions, constants, nil)
+	want := []vm.Instruction{instructions[3], instructions[4], instructions[7], instructions[8]}
+	if len(optimized) != len(want) {
+		t.Fatalf("Expected %d instructions, got %d: %v", len(want), len(optimized), optimized)
+	}
+	for pos := range want {
+		if optimized[pos] != want[pos] {
+			t.Errorf("Instruction %d: expected %+v, got %+v", pos, want[pos], optimized[pos])
+		}
+	}
+}
+
+func TestDataFlow_RemoveDeadStoresOfVariables(t *testing.T) {
+	deadAssignment := func(constants []interface{}, fn *vm.CompiledFunction) bool {
+		instructions := vm.Instructions{
+			{Opcode: vm.OpRecv, Op1: vm.ConstOperand(0), Result: vm.CVOperand(0)},
+			{Opcode: vm.OpAssign, Op2: vm.CVOperand(0), Result: vm.CVOperand(1)},
+			{Opcode: vm.OpAssign, Op2: vm.ConstOperand(0), Result: vm.CVOperand(1)},
+			{Opcode: vm.OpReturn, Op1: vm.CVOperand(1)},
+		}
+		optimized := optimizeDataFlow(instructions, constants, fn)
+		return len(optimized) == 3
+	}
+	function := func(name string) *vm.CompiledFunction {
+		return &vm.CompiledFunction{
+			Name:       name,
+			NumParams:  1,
+			Parameters: []*types.ParameterDef{{Name: "a", Type: "int"}},
+		}
+	}
+
+	if !deadAssignment([]interface{}{int64(1)}, function("f")) {
+		t.Error("The overwritten assignment of $b should be removed")
+	}
+	if deadAssignment([]interface{}{int64(1)}, nil) {
+		t.Error("Variables of the main op array are globals and should be kept")
+	}
+	if deadAssignment([]interface{}{int64(1)}, function("{closure}")) {
+		t.Error("Variables of closures may be bound by reference and should be kept")
+	}
+	if deadAssignment([]interface{}{int64(1), "compact"}, function("f")) {
+		t.Error("Variables of an op array calling compact() should be kept")
+	}
+}
+
+func TestDataFlowLevel(t *testing.T) {
+	input := `<?php
+function f(int $a) { return (int) $a; }`
+
+	compile := func(level OptimizationLevel) *vm.CompiledFunction {
+		p := parser.New(lexer.New(input, "test.php"))
+		program := p.ParseProgram()
+		c := New()
+		c.SetOptimizationLevel(level)
+		if err := c.Compile(program); err != nil {
+			t.Fatalf("Compilation failed: %v", err)
+		}
+		return c.Bytecode().Functions[0]
+	}
+
+	if _, ok := findOpcode(compile(OptimizePeephole).Instructions, vm.OpCast); !ok {
+		t.Error("-O1 should keep the cast")
+	}
+	if _, ok := findOpcode(compile(OptimizeDataFlow).Instructions, vm.OpCast); ok {
+		t.Error("-O2 should remove the cast of an int parameter to int")
+	}
+}
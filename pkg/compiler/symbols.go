@@ -1,6 +1,10 @@
 package compiler
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/types"
+)
 
 // SymbolScope represents the scope of a symbol
 type SymbolScope string
@@ -70,6 +74,7 @@ func (s *SymbolTable) Define(name string) Symbol {
 		scope = LocalScope
 	}
 
+	name = types.InternString(name)
 	symbol := Symbol{
 		Name:  name,
 		Scope: scope,
@@ -138,6 +143,18 @@ func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
 	return Symbol{}, false
 }
 
+// VariableNames returns the names of the variables defined in this scope,
+// ordered by their compiled variable index
+func (s *SymbolTable) VariableNames() []string {
+	names := make([]string, s.numDefinitions)
+	for name, symbol := range s.store {
+		if symbol.Scope == GlobalScope || symbol.Scope == LocalScope {
+			names[symbol.Index] = name
+		}
+	}
+	return names
+}
+
 // IsDefined checks if a symbol is defined in the current scope (not outer scopes)
 func (s *SymbolTable) IsDefined(name string) bool {
 	_, ok := s.store[name]
@@ -239,6 +256,17 @@ func (c *Compiler) ResolveVariable(name string) (Symbol, bool) {
 	return c.symbolTable.Resolve(name)
 }
 
+// variableSymbol returns the symbol of a variable of the function being
+// compiled, defining it on first use. PHP functions do not see the
+// variables of the script or of an enclosing function (those come in
+// through global and use), so only the current scope is searched.
+func (c *Compiler) variableSymbol(name string) Symbol {
+	if symbol, ok := c.symbolTable.store[name]; ok && symbol.Scope != BuiltinScope {
+		return symbol
+	}
+	return c.DefineVariable(name)
+}
+
 // IsVariableDefined checks if a variable is defined in the current scope
 func (c *Compiler) IsVariableDefined(name string) bool {
 	return c.symbolTable.IsDefined(name)
@@ -0,0 +1,80 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ========================================
+// Type Check and Cast Functions
+// ========================================
+
+// typeCheckFunctions are the type checking functions compiled to
+// TYPE_CHECK, with the types they accept
+var typeCheckFunctions = map[string]uint32{
+	"is_null":     vm.TypeCheckNull,
+	"is_bool":     vm.TypeCheckBool,
+	"is_int":      vm.TypeCheckInt,
+	"is_integer":  vm.TypeCheckInt,
+	"is_long":     vm.TypeCheckInt,
+	"is_float":    vm.TypeCheckFloat,
+	"is_double":   vm.TypeCheckFloat,
+	"is_string":   vm.TypeCheckString,
+	"is_array":    vm.TypeCheckArray,
+	"is_object":   vm.TypeCheckObject,
+	"is_resource": vm.TypeCheckResource,
+	"is_scalar":   vm.TypeCheckScalar,
+}
+
+// castFunctions are the conversion functions compiled to CAST, with the
+// cast target (the ExtendedValue of the cast operators)
+var castFunctions = map[string]uint32{
+	"intval":    1,
+	"boolval":   2,
+	"floatval":  3,
+	"doubleval": 3,
+	"strval":    4,
+}
+
+// compileTypeFunction compiles a call of a type checking or conversion
+// function on a single variable, is_int($x) or intval($x), to a
+// TYPE_CHECK or CAST reading the variable directly, without a function
+// call. It reports false for other calls, which are compiled as calls;
+// so are unqualified calls in a namespace, which may resolve to a
+// function of the namespace at runtime. The result is left in temp 0,
+// like a call's.
+func (c *Compiler) compileTypeFunction(node *ast.CallExpression) bool {
+	ident, ok := node.Function.(*ast.Identifier)
+	if !ok || len(node.Arguments) != 1 {
+		return false
+	}
+	variable, ok := node.Arguments[0].(*ast.Variable)
+	if !ok || variable.Name == "GLOBALS" || variable.Name == "this" {
+		return false
+	}
+	if symbol, ok := c.ResolveVariable(variable.Name); ok && symbol.Scope == BuiltinScope {
+		return false
+	}
+	name, fallback := c.namespace.ResolveFunctionName(ident.Value)
+	if fallback != "" {
+		return false
+	}
+
+	opcode := vm.OpTypeCheck
+	name = strings.ToLower(strings.TrimPrefix(name, "\\"))
+	extended, ok := typeCheckFunctions[name]
+	if !ok {
+		opcode = vm.OpCast
+		if extended, ok = castFunctions[name]; !ok {
+			return false
+		}
+	}
+	c.EmitWithExtended(opcode, uint32(node.Token.Pos.Line),
+		extended,
+		c.variableOperand(variable),
+		vm.UnusedOperand(),
+		vm.TmpVarOperand(0))
+	return true
+}
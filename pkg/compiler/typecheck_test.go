@@ -0,0 +1,58 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+func TestCompileTypeFunction(t *testing.T) {
+	tests := []struct {
+		input    string
+		opcode   vm.Opcode
+		extended uint32
+	}{
+		{"<?php is_int($x);", vm.OpTypeCheck, vm.TypeCheckInt},
+		{"<?php IS_SCALAR($x);", vm.OpTypeCheck, vm.TypeCheckScalar},
+		{"<?php is_bool($x);", vm.OpTypeCheck, vm.TypeCheckBool},
+		{"<?php intval($x);", vm.OpCast, 1},
+		{"<?php strval($x);", vm.OpCast, 4},
+		{"<?php namespace App; \\is_null($x);", vm.OpTypeCheck, vm.TypeCheckNull},
+	}
+
+	for _, tt := range tests {
+		bytecode := parseAndCompile(t, tt.input)
+		pos := positionOf(bytecode.Instructions, tt.opcode, 0)
+		if pos < 0 {
+			t.Errorf("%s: expected %s, got %v", tt.input, tt.opcode, bytecode.Instructions)
+			continue
+		}
+		instr := bytecode.Instructions[pos]
+		if instr.ExtendedValue != tt.extended || instr.Op1.Type != vm.OpCV {
+			t.Errorf("%s: expected %s %d of a compiled variable, got %v", tt.input, tt.opcode, tt.extended, instr)
+		}
+		if positionOf(bytecode.Instructions, vm.OpDoFcall, 0) >= 0 {
+			t.Errorf("%s: expected no call", tt.input)
+		}
+	}
+}
+
+func TestCompileTypeFunction_Calls(t *testing.T) {
+	// Other arguments, other arities and unqualified names in a namespace
+	// are compiled as calls
+	for _, input := range []string{
+		"<?php is_int($a[0]);",
+		"<?php is_int(f());",
+		"<?php intval($x, 16);",
+		"<?php is_numeric($x);",
+		"<?php namespace App; is_int($x);",
+	} {
+		bytecode := parseAndCompile(t, input)
+		if positionOf(bytecode.Instructions, vm.OpDoFcall, 0) < 0 {
+			t.Errorf("%s: expected a call, got %v", input, bytecode.Instructions)
+		}
+		if positionOf(bytecode.Instructions, vm.OpTypeCheck, 0) >= 0 {
+			t.Errorf("%s: expected no TYPE_CHECK", input)
+		}
+	}
+}
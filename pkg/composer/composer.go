@@ -0,0 +1,202 @@
+// Package composer installs the autoloading of a Composer project in a VM
+// without running Composer's own autoloader. It reads the files Composer
+// generates in vendor/composer — autoload_psr4.php, autoload_classmap.php
+// and autoload_files.php — and registers the equivalent PSR-4 prefixes and
+// class map with the VM. The project's vendor/autoload.php is replaced by
+// a script requiring the files of autoload_files.php, so requiring it
+// loads them as Composer would.
+package composer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// Autoload is the autoloading Composer generated for a project
+type Autoload struct {
+	VendorDir string
+	Psr4      []Namespace       // In the order of autoload_psr4.php, longest prefixes first
+	ClassMap  map[string]string // Class name => file declaring it
+	Files     []string          // Files required by vendor/autoload.php, in order
+}
+
+// Namespace is a PSR-4 namespace prefix and its base directories
+type Namespace struct {
+	Prefix string
+	Dirs   []string
+}
+
+// Find returns the root of the Composer project containing a directory:
+// the nearest directory, from dir up, holding composer.json or a vendor
+// directory with Composer's generated files
+func Find(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		if isFile(filepath.Join(dir, "composer.json")) || isFile(filepath.Join(dir, "vendor", "composer", "autoload_psr4.php")) {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// Load reads the autoloading of the Composer project at a directory. The
+// vendor directory is the config.vendor-dir of composer.json, "vendor" by
+// default, and must hold Composer's generated files: run composer install
+// or composer dump-autoload first.
+func Load(projectDir string) (*Autoload, error) {
+	projectDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	vendorDir, err := vendorDir(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	generated := filepath.Join(vendorDir, "composer")
+	if !isFile(filepath.Join(generated, "autoload_psr4.php")) {
+		return nil, fmt.Errorf("%s has no Composer autoload files; run composer dump-autoload", vendorDir)
+	}
+
+	a := &Autoload{VendorDir: vendorDir, ClassMap: make(map[string]string)}
+
+	psr4, err := evaluateOptional(filepath.Join(generated, "autoload_psr4.php"))
+	if err != nil {
+		return nil, err
+	}
+	for _, ns := range psr4 {
+		dirs, err := paths(ns.value)
+		if err != nil {
+			return nil, fmt.Errorf("autoload_psr4.php: namespace %s: %w", ns.key, err)
+		}
+		a.Psr4 = append(a.Psr4, Namespace{Prefix: ns.key, Dirs: dirs})
+	}
+
+	classMap, err := evaluateOptional(filepath.Join(generated, "autoload_classmap.php"))
+	if err != nil {
+		return nil, err
+	}
+	for _, class := range classMap {
+		path, ok := class.value.(string)
+		if !ok {
+			return nil, fmt.Errorf("autoload_classmap.php: class %s: the file is not a path", class.key)
+		}
+		a.ClassMap[class.key] = path
+	}
+
+	files, err := evaluateOptional(filepath.Join(generated, "autoload_files.php"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		path, ok := file.value.(string)
+		if !ok {
+			return nil, fmt.Errorf("autoload_files.php: file %s is not a path", file.key)
+		}
+		a.Files = append(a.Files, path)
+	}
+	return a, nil
+}
+
+// Install registers the autoloading with a VM: the class map, which
+// Composer consults first, then the PSR-4 prefixes. Requiring the
+// project's vendor/autoload.php requires the autoloaded files, once, and
+// returns true rather than Composer's ClassLoader.
+func (a *Autoload) Install(machine *vm.VM) {
+	if len(a.ClassMap) > 0 {
+		machine.AddClassMap(a.ClassMap)
+	}
+	for _, ns := range a.Psr4 {
+		for _, dir := range ns.Dirs {
+			machine.AddPsr4Namespace(ns.Prefix, dir)
+		}
+	}
+	machine.ReplaceFile(filepath.Join(a.VendorDir, "autoload.php"), a.autoloadScript())
+}
+
+// autoloadScript returns the script replacing vendor/autoload.php
+func (a *Autoload) autoloadScript() []byte {
+	var script strings.Builder
+	script.WriteString("<?php\n// Autoloading installed by php-go from vendor/composer\n")
+	for _, file := range a.Files {
+		script.WriteString("require_once " + quote(file) + ";\n")
+	}
+	script.WriteString("return true;\n")
+	return []byte(script.String())
+}
+
+// quote returns a single-quoted PHP string literal
+func quote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// vendorDir returns the vendor directory of a project
+func vendorDir(projectDir string) (string, error) {
+	dir := "vendor"
+	data, err := os.ReadFile(filepath.Join(projectDir, "composer.json"))
+	switch {
+	case err == nil:
+		var manifest struct {
+			Config struct {
+				VendorDir string `json:"vendor-dir"`
+			} `json:"config"`
+		}
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return "", fmt.Errorf("composer.json: %w", err)
+		}
+		if manifest.Config.VendorDir != "" {
+			dir = manifest.Config.VendorDir
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return "", err
+	}
+	if filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	return filepath.Join(projectDir, dir), nil
+}
+
+// evaluateOptional evaluates a generated file that Composer may omit
+func evaluateOptional(path string) ([]entry, error) {
+	if !isFile(path) {
+		return nil, nil
+	}
+	return evaluateFile(path)
+}
+
+// paths converts a path or an array of paths
+func paths(value interface{}) ([]string, error) {
+	switch value := value.(type) {
+	case string:
+		return []string{value}, nil
+	case []entry:
+		list := make([]string, 0, len(value))
+		for i, element := range value {
+			path, ok := element.value.(string)
+			if !ok {
+				return nil, fmt.Errorf("directory %d is not a path", i)
+			}
+			list = append(list, path)
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("the directories are not paths")
+}
+
+// isFile reports whether a regular file exists
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,173 @@
+package composer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/compiler"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// writeFiles creates files under a directory
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// project creates a Composer project with the autoload files Composer
+// generates for a PSR-4 package, a class map and a file
+func project(t *testing.T) string {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"composer.json": `{"autoload": {"psr-4": {"App\\": ["src/", "lib/"]}}}`,
+		"vendor/composer/autoload_psr4.php": `<?php
+
+// autoload_psr4.php @generated by Composer
+
+$vendorDir = dirname(__DIR__);
+$baseDir = dirname($vendorDir);
+
+return array(
+    'Acme\\Util\\' => array($vendorDir . '/acme/util/src'),
+    'App\\' => array($baseDir . '/src', $baseDir . '/lib'),
+);
+`,
+		"vendor/composer/autoload_classmap.php": `<?php
+
+// autoload_classmap.php @generated by Composer
+
+$vendorDir = dirname(dirname(__FILE__));
+$baseDir = dirname($vendorDir);
+
+return array(
+    'Composer\\InstalledVersions' => $vendorDir . '/composer/InstalledVersions.php',
+    'Legacy_Helper' => $baseDir . '/legacy/Helper.php',
+);
+`,
+		"vendor/composer/autoload_files.php": `<?php
+
+// autoload_files.php @generated by Composer
+
+$vendorDir = dirname(__DIR__);
+$baseDir = dirname($vendorDir);
+
+return [
+    '0e6d7bf4a5811bfa5cf40c5ccd6fae6a' => $vendorDir . '/acme/util/functions.php',
+];
+`,
+		"vendor/autoload.php":            "<?php\nthrow new Exception('Composer autoloader');\n",
+		"vendor/acme/util/functions.php": "<?php\necho \"functions;\";\n",
+		"vendor/acme/util/src/Text.php":  "<?php\nnamespace Acme\\Util;\necho \"text;\";\nclass Text {}\n",
+		"lib/Greeter.php":                "<?php\nnamespace App;\necho \"greeter;\";\nclass Greeter {}\n",
+		"legacy/Helper.php":              "<?php\necho \"helper;\";\nclass Legacy_Helper {}\n",
+	})
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := project(t)
+	vendor := filepath.Join(dir, "vendor")
+
+	a, err := Load(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	psr4 := []Namespace{
+		{Prefix: "Acme\\Util\\", Dirs: []string{vendor + "/acme/util/src"}},
+		{Prefix: "App\\", Dirs: []string{dir + "/src", dir + "/lib"}},
+	}
+	if !reflect.DeepEqual(a.Psr4, psr4) {
+		t.Errorf("Expected PSR-4 prefixes %v, got %v", psr4, a.Psr4)
+	}
+	classMap := map[string]string{
+		"Composer\\InstalledVersions": vendor + "/composer/InstalledVersions.php",
+		"Legacy_Helper":               dir + "/legacy/Helper.php",
+	}
+	if !reflect.DeepEqual(a.ClassMap, classMap) {
+		t.Errorf("Expected class map %v, got %v", classMap, a.ClassMap)
+	}
+	if files := []string{vendor + "/acme/util/functions.php"}; !reflect.DeepEqual(a.Files, files) {
+		t.Errorf("Expected files %v, got %v", files, a.Files)
+	}
+}
+
+func TestLoad_VendorDirAndErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"composer.json":                   `{"config": {"vendor-dir": "deps"}}`,
+		"deps/composer/autoload_psr4.php": "<?php\nreturn array('App\\\\' => array(__DIR__ . '/../../src'));\n",
+	})
+	a, err := Load(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.VendorDir != filepath.Join(dir, "deps") || len(a.ClassMap) != 0 || a.Files != nil {
+		t.Errorf("Expected the deps vendor directory without a class map or files, got %+v", a)
+	}
+
+	if _, err := Load(t.TempDir()); err == nil || !strings.Contains(err.Error(), "dump-autoload") {
+		t.Errorf("Expected an error for a project without autoload files, got %v", err)
+	}
+
+	writeFiles(t, dir, map[string]string{
+		"deps/composer/autoload_psr4.php": "<?php\nreturn array('App\\\\' => array(getenv('SRC')));\n",
+	})
+	if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), "autoload_psr4.php:2: unsupported") {
+		t.Errorf("Expected an error for code Composer does not generate, got %v", err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := project(t)
+	nested := filepath.Join(dir, "public", "admin")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if root, ok := Find(nested); !ok || root != dir {
+		t.Errorf("Expected the project root %s, got %q", dir, root)
+	}
+}
+
+func TestInstall(t *testing.T) {
+	dir := project(t)
+	a, err := Load(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	compile := compiler.ScriptCompiler(compiler.OptimizeNone)
+	path := filepath.Join(dir, "index.php")
+	script, err := compile(path, []byte(`<?php
+require `+quote(filepath.Join(dir, "vendor", "autoload.php"))+`;
+require_once `+quote(filepath.Join(dir, "vendor", "autoload.php"))+`;
+echo "main;";
+new App\Greeter();
+new Acme\Util\Text();
+new Legacy_Helper();
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	machine := vm.New()
+	machine.SetScriptCompiler(compile)
+	a.Install(machine)
+	if err := machine.ExecuteScript(script); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output := machine.GetOutput(); output != "functions;main;greeter;text;helper;" {
+		t.Errorf("Expected the files, then the classes to load, got %q", output)
+	}
+}
@@ -0,0 +1,225 @@
+package composer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+// ============================================================================
+// Evaluating Generated Files
+// ============================================================================
+
+// entry is an element of an array literal. Elements without a key get the
+// next integer key, as in PHP.
+type entry struct {
+	key   string
+	value interface{} // string or []entry
+}
+
+// evaluator evaluates the PHP of Composer's generated autoload files
+// without running it: assignments of path expressions to variables,
+// followed by the return of an array. A path expression concatenates
+// string literals, variables, __DIR__, __FILE__ and calls of dirname().
+type evaluator struct {
+	file string
+	l    *lexer.Lexer
+	tok  lexer.Token
+	vars map[string]string
+}
+
+// evaluateFile returns the array a generated autoload file returns
+func evaluateFile(path string) ([]entry, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	e := &evaluator{file: abs, l: lexer.New(string(source), abs), vars: make(map[string]string)}
+	e.next()
+	return e.evaluate()
+}
+
+// next advances to the next token, skipping comments
+func (e *evaluator) next() {
+	e.tok = e.l.NextToken()
+	for e.tok.Type == lexer.COMMENT || e.tok.Type == lexer.DOC_COMMENT {
+		e.tok = e.l.NextToken()
+	}
+}
+
+// errorf reports an error at the current token
+func (e *evaluator) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d: %s", e.file, e.tok.Pos.Line, fmt.Sprintf(format, args...))
+}
+
+// expect consumes a token of a type
+func (e *evaluator) expect(typ lexer.TokenType) error {
+	if e.tok.Type != typ {
+		return e.errorf("unexpected %q, expected %q", e.tok.Literal, typ)
+	}
+	e.next()
+	return nil
+}
+
+// evaluate runs the statements of the file up to its return
+func (e *evaluator) evaluate() ([]entry, error) {
+	for {
+		switch e.tok.Type {
+		case lexer.OPEN_TAG:
+			e.next()
+		case lexer.VARIABLE:
+			name := e.tok.Literal
+			e.next()
+			if err := e.expect(lexer.ASSIGN); err != nil {
+				return nil, err
+			}
+			value, err := e.path()
+			if err != nil {
+				return nil, err
+			}
+			e.vars[name] = value
+			if err := e.expect(lexer.SEMICOLON); err != nil {
+				return nil, err
+			}
+		case lexer.RETURN:
+			e.next()
+			return e.array()
+		case lexer.EOF:
+			return nil, e.errorf("no array returned")
+		default:
+			return nil, e.errorf("unsupported %q", e.tok.Literal)
+		}
+	}
+}
+
+// value evaluates a path expression or an array literal
+func (e *evaluator) value() (interface{}, error) {
+	if e.tok.Type == lexer.ARRAY || e.tok.Type == lexer.LBRACKET {
+		return e.array()
+	}
+	return e.path()
+}
+
+// array evaluates an array literal: array(...) or [...]
+func (e *evaluator) array() ([]entry, error) {
+	closing := lexer.RBRACKET
+	if e.tok.Type == lexer.ARRAY {
+		e.next()
+		closing = lexer.RPAREN
+		if err := e.expect(lexer.LPAREN); err != nil {
+			return nil, err
+		}
+	} else if err := e.expect(lexer.LBRACKET); err != nil {
+		return nil, err
+	}
+
+	entries := []entry{}
+	index := 0
+	for e.tok.Type != closing {
+		value, err := e.value()
+		if err != nil {
+			return nil, err
+		}
+		key := ""
+		if e.tok.Type == lexer.DOUBLE_ARROW {
+			e.next()
+			var ok bool
+			if key, ok = value.(string); !ok {
+				return nil, e.errorf("array key is not a string")
+			}
+			if value, err = e.value(); err != nil {
+				return nil, err
+			}
+		} else {
+			key = strconv.Itoa(index)
+			index++
+		}
+		entries = append(entries, entry{key: key, value: value})
+
+		if e.tok.Type != lexer.COMMA {
+			break
+		}
+		e.next()
+	}
+	return entries, e.expect(closing)
+}
+
+// path evaluates a concatenation of path terms
+func (e *evaluator) path() (string, error) {
+	value, err := e.term()
+	if err != nil {
+		return "", err
+	}
+	for e.tok.Type == lexer.CONCAT || e.tok.Type == lexer.DOT {
+		e.next()
+		term, err := e.term()
+		if err != nil {
+			return "", err
+		}
+		value += term
+	}
+	return value, nil
+}
+
+// term evaluates a string literal, a variable, __DIR__, __FILE__ or a
+// call of dirname()
+func (e *evaluator) term() (string, error) {
+	tok := e.tok
+	switch tok.Type {
+	case lexer.STRING:
+		e.next()
+		return tok.Literal, nil
+	case lexer.DIR_CONST:
+		e.next()
+		return filepath.Dir(e.file), nil
+	case lexer.FILE_CONST:
+		e.next()
+		return e.file, nil
+	case lexer.VARIABLE:
+		value, ok := e.vars[tok.Literal]
+		if !ok {
+			return "", e.errorf("undefined variable %s", tok.Literal)
+		}
+		e.next()
+		return value, nil
+	case lexer.IDENT:
+		if tok.Literal == "dirname" {
+			return e.dirname()
+		}
+	}
+	return "", e.errorf("unsupported %q", tok.Literal)
+}
+
+// dirname evaluates dirname($path) and dirname($path, $levels)
+func (e *evaluator) dirname() (string, error) {
+	e.next()
+	if err := e.expect(lexer.LPAREN); err != nil {
+		return "", err
+	}
+	value, err := e.path()
+	if err != nil {
+		return "", err
+	}
+	levels := 1
+	if e.tok.Type == lexer.COMMA {
+		e.next()
+		if e.tok.Type != lexer.INTEGER {
+			return "", e.errorf("unsupported dirname() levels %q", e.tok.Literal)
+		}
+		if levels, err = strconv.Atoi(e.tok.Literal); err != nil || levels < 1 {
+			return "", e.errorf("invalid dirname() levels %q", e.tok.Literal)
+		}
+		e.next()
+	}
+	for i := 0; i < levels; i++ {
+		value = filepath.Dir(value)
+	}
+	return value, e.expect(lexer.RPAREN)
+}
@@ -0,0 +1,75 @@
+package dbgp
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// Configuration
+// ============================================================================
+
+// DefaultPort is the port IDEs listen on for debugging connections
+const DefaultPort = 9003
+
+// Config tells where the IDE listens
+type Config struct {
+	Host   string
+	Port   int
+	IDEKey string // Key the IDE matches the session against
+}
+
+// Address returns the host and port to connect to
+func (c Config) Address() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// ConfigFromEnv reads the configuration from the environment as Xdebug
+// does, reporting whether a debugging session is requested:
+// XDEBUG_SESSION=<idekey> (or XDEBUG_SESSION_START) starts one, as does
+// XDEBUG_MODE=debug with start_with_request=yes in XDEBUG_CONFIG.
+// XDEBUG_CONFIG holds space-separated settings such as
+// "client_host=10.0.0.2 client_port=9000 idekey=PHPSTORM".
+func ConfigFromEnv(getenv func(string) string) (Config, bool) {
+	cfg := Config{Host: "localhost", Port: DefaultPort}
+	settings := map[string]string{}
+	for _, field := range strings.Fields(getenv("XDEBUG_CONFIG")) {
+		if name, value, ok := strings.Cut(field, "="); ok {
+			settings[name] = value
+		}
+	}
+
+	for _, name := range []string{"client_host", "remote_host"} {
+		if host := settings[name]; host != "" {
+			cfg.Host = host
+			break
+		}
+	}
+	for _, name := range []string{"client_port", "remote_port"} {
+		if port, err := strconv.Atoi(settings[name]); err == nil && port > 0 {
+			cfg.Port = port
+			break
+		}
+	}
+	cfg.IDEKey = settings["idekey"]
+
+	trigger := getenv("XDEBUG_SESSION")
+	if trigger == "" {
+		trigger = getenv("XDEBUG_SESSION_START")
+	}
+	if trigger != "" && cfg.IDEKey == "" {
+		cfg.IDEKey = trigger
+	}
+
+	debugMode := false
+	for _, mode := range strings.Split(getenv("XDEBUG_MODE"), ",") {
+		if strings.TrimSpace(mode) == "debug" {
+			debugMode = true
+		}
+	}
+	if getenv("XDEBUG_MODE") != "" && !debugMode {
+		return cfg, false
+	}
+	return cfg, trigger != "" || (debugMode && settings["start_with_request"] == "yes")
+}
@@ -0,0 +1,257 @@
+package dbgp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Properties
+// ============================================================================
+
+// property describes a value for the IDE. Arrays and objects list the
+// page of their children given, max_children per page, down to depth
+// levels; strings are cut to max_data bytes.
+func (s *Session) property(value *types.Value, name, fullname string, depth, page int) *property {
+	value = value.Deref()
+	prop := &property{Name: name, Fullname: fullname, Type: typeName(value)}
+
+	switch value.Type() {
+	case types.TypeBool:
+		prop.Value = "0"
+		if value.ToBool() {
+			prop.Value = "1"
+		}
+	case types.TypeInt:
+		prop.Value = strconv.FormatInt(value.ToInt(), 10)
+	case types.TypeFloat:
+		prop.Value = strconv.FormatFloat(value.ToFloat(), 'G', -1, 64)
+	case types.TypeString:
+		str := value.ToString()
+		prop.Size = strconv.Itoa(len(str))
+		if maxData := s.feature("max_data"); maxData > 0 && len(str) > maxData {
+			str = str[:maxData]
+		}
+		prop.Encoding = "base64"
+		prop.Value = base64.StdEncoding.EncodeToString([]byte(str))
+	case types.TypeResource:
+		resource := value.ToResource()
+		prop.Value = fmt.Sprintf("resource id='%d' type='%s'", resource.ID(), resource.TypeName())
+	case types.TypeArray:
+		arr := value.ToArray()
+		var children []*property
+		if depth > 0 {
+			index := 0
+			arr.Each(func(key, child *types.Value) bool {
+				if s.onPage(index, page) {
+					children = append(children, s.property(child, key.ToString(), elementName(fullname, key), depth-1, 0))
+				}
+				index++
+				return true
+			})
+		}
+		s.setChildren(prop, arr.Len(), page, children)
+	case types.TypeObject:
+		obj := value.ToObject()
+		prop.Classname = obj.ClassName
+		var children []*property
+		props := obj.DebugProperties()
+		if depth > 0 {
+			for index, p := range props {
+				if s.onPage(index, page) {
+					name := p.Key.ToString()
+					child := s.property(p.Value, name, fullname+"->"+name, depth-1, 0)
+					child.Facet = p.Visibility.String()
+					children = append(children, child)
+				}
+			}
+		}
+		s.setChildren(prop, len(props), page, children)
+	}
+	return prop
+}
+
+// onPage reports whether the child at an index is on a page
+func (s *Session) onPage(index, page int) bool {
+	size := s.feature("max_children")
+	return size <= 0 || index/size == page
+}
+
+// setChildren sets the children of an array or object property
+func (s *Session) setChildren(prop *property, count, page int, children []*property) {
+	prop.Children = "0"
+	if count > 0 {
+		prop.Children = "1"
+	}
+	prop.NumChildren = strconv.Itoa(count)
+	prop.Page = strconv.Itoa(page)
+	prop.PageSize = strconv.Itoa(s.feature("max_children"))
+	prop.Properties = children
+}
+
+// typeName returns the DBGp name of the type of a value
+func typeName(value *types.Value) string {
+	switch value.Type() {
+	case types.TypeUndef:
+		return "uninitialized"
+	case types.TypeNull:
+		return "null"
+	case types.TypeBool:
+		return "bool"
+	case types.TypeInt:
+		return "int"
+	case types.TypeFloat:
+		return "float"
+	case types.TypeString:
+		return "string"
+	case types.TypeArray:
+		return "array"
+	case types.TypeObject:
+		return "object"
+	case types.TypeResource:
+		return "resource"
+	}
+	return "unknown"
+}
+
+// elementName returns the full name of an array element: $a[1] or
+// $a["key"], escaped for a PHP double-quoted string
+func elementName(fullname string, key *types.Value) string {
+	if key.IsInt() {
+		return fmt.Sprintf("%s[%d]", fullname, key.ToInt())
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(key.ToString())
+	return fmt.Sprintf(`%s["%s"]`, fullname, escaped)
+}
+
+// ============================================================================
+// Full Names
+// ============================================================================
+
+// lookup returns the value of a variable given by its full name in the
+// frame at a depth: a variable, then elements ["key"] or [1] and
+// properties ->name, which reach properties of any visibility. Other
+// expressions are evaluated.
+func (s *Session) lookup(fullname string, depth int) (*types.Value, error) {
+	if value, ok := s.walk(fullname, depth); ok {
+		return value, nil
+	}
+	return s.debugger.Eval(fullname, depth)
+}
+
+// walk resolves a full name made of a variable, elements and properties,
+// reporting false for anything else
+func (s *Session) walk(fullname string, depth int) (*types.Value, bool) {
+	if !strings.HasPrefix(fullname, "$") {
+		return nil, false
+	}
+	name, rest := splitIdentifier(fullname[1:])
+	if name == "" {
+		return nil, false
+	}
+	value, ok := s.variable(name, depth)
+	if !ok {
+		return nil, false
+	}
+
+	for rest != "" {
+		value = value.Deref()
+		switch {
+		case strings.HasPrefix(rest, "->"):
+			name, rest = splitIdentifier(rest[2:])
+			if name == "" || !value.IsObject() {
+				return nil, false
+			}
+			prop, ok := value.ToObject().Properties[name]
+			if !ok || prop.Value == nil {
+				return nil, false
+			}
+			value = prop.Value
+		case strings.HasPrefix(rest, "["):
+			key, remaining, ok := parseKey(rest[1:])
+			if !ok || !value.IsArray() {
+				return nil, false
+			}
+			if value, ok = value.ToArray().Get(key); !ok {
+				return nil, false
+			}
+			rest = remaining
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// variable returns a variable of the frame at a depth, or its $this
+func (s *Session) variable(name string, depth int) (*types.Value, bool) {
+	if name == "this" {
+		this, err := s.debugger.This(depth)
+		if err != nil || this == nil {
+			return nil, false
+		}
+		return types.NewObject(this), true
+	}
+	locals, err := s.debugger.Locals(depth)
+	if err != nil {
+		return nil, false
+	}
+	for _, local := range locals {
+		if local.Name == name {
+			return local.Value, true
+		}
+	}
+	return nil, false
+}
+
+// splitIdentifier splits the name at the start of s from the rest
+func splitIdentifier(s string) (string, string) {
+	end := 0
+	for end < len(s) {
+		ch := s[end]
+		if ch != '_' && ch < 0x80 && !('a' <= ch && ch <= 'z') && !('A' <= ch && ch <= 'Z') && !('0' <= ch && ch <= '9') {
+			break
+		}
+		end++
+	}
+	return s[:end], s[end:]
+}
+
+// parseKey parses the key of an element after its "[": an integer or a
+// double-quoted string, then "]"
+func parseKey(s string) (*types.Value, string, bool) {
+	if strings.HasPrefix(s, `"`) {
+		var key strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				if i+1 < len(s) {
+					i++
+					key.WriteByte(s[i])
+				}
+			case '"':
+				if !strings.HasPrefix(s[i+1:], "]") {
+					return nil, "", false
+				}
+				return types.NewString(key.String()), s[i+2:], true
+			default:
+				key.WriteByte(s[i])
+			}
+		}
+		return nil, "", false
+	}
+
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return nil, "", false
+	}
+	n, err := strconv.ParseInt(s[:end], 10, 64)
+	if err != nil {
+		return nil, "", false
+	}
+	return types.NewInt(n), s[end+1:], true
+}
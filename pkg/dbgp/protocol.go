@@ -0,0 +1,280 @@
+// Package dbgp implements the DBGp debugging protocol, as spoken by Xdebug,
+// over php-go's debugger: the script connects to the IDE (PhpStorm, VS
+// Code...), which sets breakpoints, steps, inspects the variables and
+// evaluates code.
+package dbgp
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ============================================================================
+// Framing
+// ============================================================================
+
+// Namespaces of the DBGp messages
+const (
+	namespace       = "urn:debugger_protocol_v1"
+	xdebugNamespace = "https://xdebug.org/dbgp/xdebug"
+)
+
+// writePacket writes a message to the IDE: its length, a NUL byte, the
+// XML document and a NUL byte
+func writePacket(w io.Writer, message interface{}) error {
+	body, err := xml.Marshal(message)
+	if err != nil {
+		return err
+	}
+	data := `<?xml version="1.0" encoding="iso-8859-1"?>` + "\n" + string(body)
+	_, err = fmt.Fprintf(w, "%d\x00%s\x00", len(data), data)
+	return err
+}
+
+// command is a command from the IDE: "breakpoint_set -i 3 -t line -n 12",
+// with data after "--" for some commands
+type command struct {
+	name        string
+	transaction string
+	args        map[string]string
+	data        string // Decoded data after "--"
+	err         error  // Why the command could not be parsed
+}
+
+// readCommand reads a command, which the IDE terminates with a NUL byte.
+// A command that does not parse is returned with its error, to be answered.
+func readCommand(r *bufio.Reader) (*command, error) {
+	line, err := r.ReadString(0)
+	if err != nil {
+		return nil, err
+	}
+	cmd, err := parseCommand(strings.TrimSuffix(line, "\x00"))
+	if err != nil {
+		return &command{err: err}, nil
+	}
+	return cmd, nil
+}
+
+// parseCommand parses a command line: its name, then options of a dash and
+// a letter each followed by a value, possibly quoted, then "--" and the
+// base64 data
+func parseCommand(line string) (*command, error) {
+	words, err := splitArgs(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+
+	cmd := &command{name: words[0], args: map[string]string{}}
+	for i := 1; i < len(words); i++ {
+		word := words[i]
+		if word == "--" {
+			data, err := base64.StdEncoding.DecodeString(strings.Join(words[i+1:], ""))
+			if err != nil {
+				return nil, fmt.Errorf("invalid data: %v", err)
+			}
+			cmd.data = string(data)
+			break
+		}
+		if len(word) != 2 || word[0] != '-' {
+			return nil, fmt.Errorf("invalid option %q", word)
+		}
+		value := ""
+		if i+1 < len(words) {
+			i++
+			value = words[i]
+		}
+		cmd.args[word[1:]] = value
+	}
+	cmd.transaction = cmd.args["i"]
+	return cmd, nil
+}
+
+// splitArgs splits a command line on spaces, a double-quoted word keeping
+// its spaces and backslash escapes
+func splitArgs(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, quoted := false, false
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case quoted && ch == '\\' && i+1 < len(line):
+			i++
+			word.WriteByte(line[i])
+		case quoted && ch == '"':
+			quoted = false
+		case quoted:
+			word.WriteByte(ch)
+		case ch == '"':
+			quoted, inWord = true, true
+		case ch == ' ':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(ch)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quoted argument")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// ============================================================================
+// Messages
+// ============================================================================
+
+// Error codes of the DBGp responses
+const (
+	errParse             = 1
+	errFileOpen          = 100
+	errInvalidOptions    = 3
+	errUnimplemented     = 4
+	errUnavailable       = 5
+	errBreakpointInvalid = 200
+	errBreakpointType    = 201
+	errNoBreakpoint      = 205
+	errEvaluation        = 206
+	errNoProperty        = 300
+	errStackDepth        = 301
+	errContext           = 302
+)
+
+// initPacket is the first message, sent when the script connects
+type initPacket struct {
+	XMLName         xml.Name `xml:"init"`
+	Xmlns           string   `xml:"xmlns,attr"`
+	XmlnsXdebug     string   `xml:"xmlns:xdebug,attr"`
+	FileURI         string   `xml:"fileuri,attr"`
+	Language        string   `xml:"language,attr"`
+	ProtocolVersion string   `xml:"protocol_version,attr"`
+	AppID           string   `xml:"appid,attr"`
+	IDEKey          string   `xml:"idekey,attr"`
+	Engine          engine   `xml:"engine"`
+}
+
+// engine names the debugger engine in the init packet
+type engine struct {
+	Version string `xml:"version,attr"`
+	Name    string `xml:",cdata"`
+}
+
+// response answers a command. Its attributes and content depend on the
+// command.
+type response struct {
+	XMLName     xml.Name      `xml:"response"`
+	Xmlns       string        `xml:"xmlns,attr"`
+	XmlnsXdebug string        `xml:"xmlns:xdebug,attr"`
+	Command     string        `xml:"command,attr"`
+	Transaction string        `xml:"transaction_id,attr"`
+	Attrs       []xml.Attr    `xml:",any,attr"`
+	Children    []interface{} // Elements, such as properties
+	Value       string        `xml:",cdata"`
+}
+
+// newResponse creates the response to a command
+func newResponse(cmd *command) *response {
+	return &response{Xmlns: namespace, XmlnsXdebug: xdebugNamespace, Command: cmd.name, Transaction: cmd.transaction}
+}
+
+// attr adds an attribute to the response
+func (r *response) attr(name, value string) *response {
+	r.Attrs = append(r.Attrs, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+	return r
+}
+
+// errorElement is the error a failed command responds with
+type errorElement struct {
+	XMLName xml.Name `xml:"error"`
+	Code    int      `xml:"code,attr"`
+	Message string   `xml:"message"`
+}
+
+// message tells the IDE where the execution stopped
+type message struct {
+	XMLName  xml.Name `xml:"xdebug:message"`
+	Filename string   `xml:"filename,attr"`
+	Lineno   int      `xml:"lineno,attr"`
+}
+
+// stream copies the output of the script to the IDE
+type stream struct {
+	XMLName  xml.Name `xml:"stream"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Type     string   `xml:"type,attr"`
+	Encoding string   `xml:"encoding,attr"`
+	Data     string   `xml:",chardata"`
+}
+
+// stackElement is a frame of the call stack
+type stackElement struct {
+	XMLName  xml.Name `xml:"stack"`
+	Where    string   `xml:"where,attr"`
+	Level    int      `xml:"level,attr"`
+	Type     string   `xml:"type,attr"`
+	Filename string   `xml:"filename,attr"`
+	Lineno   int      `xml:"lineno,attr"`
+}
+
+// contextElement names a context of variables
+type contextElement struct {
+	XMLName xml.Name `xml:"context"`
+	Name    string   `xml:"name,attr"`
+	ID      int      `xml:"id,attr"`
+}
+
+// breakpointElement describes a breakpoint
+type breakpointElement struct {
+	XMLName      xml.Name `xml:"breakpoint"`
+	ID           int      `xml:"id,attr"`
+	Type         string   `xml:"type,attr"`
+	State        string   `xml:"state,attr"`
+	Filename     string   `xml:"filename,attr,omitempty"`
+	Lineno       int      `xml:"lineno,attr,omitempty"`
+	Function     string   `xml:"function,attr,omitempty"`
+	HitCount     int      `xml:"hit_count,attr"`
+	HitValue     int      `xml:"hit_value,attr"`
+	HitCondition string   `xml:"hit_condition,attr"`
+	Expression   string   `xml:"expression,omitempty"`
+}
+
+// typeMap maps a PHP type to its XML Schema type
+type typeMap struct {
+	XMLName xml.Name `xml:"map"`
+	Name    string   `xml:"name,attr"`
+	Type    string   `xml:"type,attr"`
+	XSIType string   `xml:"xsi:type,attr,omitempty"`
+}
+
+// property is a variable, or an element or property of one, with its
+// children when it is an array or an object
+type property struct {
+	XMLName     xml.Name    `xml:"property"`
+	Name        string      `xml:"name,attr,omitempty"`
+	Fullname    string      `xml:"fullname,attr,omitempty"`
+	Type        string      `xml:"type,attr"`
+	Classname   string      `xml:"classname,attr,omitempty"`
+	Facet       string      `xml:"facet,attr,omitempty"`
+	Size        string      `xml:"size,attr,omitempty"`
+	Children    string      `xml:"children,attr,omitempty"`
+	NumChildren string      `xml:"numchildren,attr,omitempty"`
+	Page        string      `xml:"page,attr,omitempty"`
+	PageSize    string      `xml:"pagesize,attr,omitempty"`
+	Encoding    string      `xml:"encoding,attr,omitempty"`
+	Properties  []*property `xml:"property"`
+	Value       string      `xml:",cdata"`
+}
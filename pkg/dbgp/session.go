@@ -0,0 +1,758 @@
+package dbgp
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ============================================================================
+// Session
+// ============================================================================
+
+// ErrStopped is returned by the execution when the IDE stops the script
+var ErrStopped = errors.New("dbgp: script stopped by the debugging client")
+
+// connectTimeout bounds the wait for the IDE to accept the connection
+const connectTimeout = 5 * time.Second
+
+// Session is a debugging session with an IDE. It drives the debugger of a
+// VM: the script runs until a breakpoint or a step stops it, when the IDE's
+// commands are answered until one resumes the execution.
+type Session struct {
+	conn     io.ReadWriteCloser
+	reader   *bufio.Reader
+	machine  *vm.VM
+	debugger *vm.Debugger
+	closed   bool
+
+	status   string         // starting, running, break, stopping or stopped
+	pending  *command       // Command resuming the execution, answered when it stops
+	action   vm.DebugAction // How the execution last resumed
+	features map[string]string
+	stdout   int // Output to the IDE: 0 none, 1 a copy, 2 redirected
+
+	breakpoints map[int]*breakpoint // DBGp settings of the breakpoints by ID
+}
+
+// breakpoint holds the DBGp settings of a breakpoint of the debugger
+type breakpoint struct {
+	bp           *vm.Breakpoint
+	kind         string // line, conditional or call
+	enabled      bool
+	temporary    bool   // Removed once hit
+	condition    string // Expression that must be true to stop
+	hitValue     int
+	hitCondition string // >=, == or %
+}
+
+// Connect connects to the IDE and attaches a session to a VM about to run a
+// script
+func Connect(cfg Config, machine *vm.VM, script string) (*Session, error) {
+	conn, err := net.DialTimeout("tcp", cfg.Address(), connectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return Attach(conn, machine, script, cfg.IDEKey)
+}
+
+// Attach starts a session over a connection to the IDE, sending the init
+// packet. The script stops on its first line for the IDE to set up the
+// session.
+func Attach(conn io.ReadWriteCloser, machine *vm.VM, script, ideKey string) (*Session, error) {
+	s := &Session{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		machine: machine,
+		status:  "starting",
+		features: map[string]string{
+			"max_children":         "32",
+			"max_data":             "1024",
+			"max_depth":            "1",
+			"show_hidden":          "1",
+			"extended_properties":  "0",
+			"notify_ok":            "0",
+			"resolved_breakpoints": "0",
+			"breakpoint_details":   "0",
+			"multiple_sessions":    "0",
+			"encoding":             "iso-8859-1",
+		},
+		breakpoints: map[int]*breakpoint{},
+	}
+	err := writePacket(conn, &initPacket{
+		Xmlns:           namespace,
+		XmlnsXdebug:     xdebugNamespace,
+		FileURI:         fileURI(script),
+		Language:        "PHP",
+		ProtocolVersion: "1.0",
+		AppID:           strconv.Itoa(os.Getpid()),
+		IDEKey:          ideKey,
+		Engine:          engine{Version: s.phpVersion(), Name: "php-go"},
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	s.debugger = vm.NewDebugger(machine, s)
+	return s, nil
+}
+
+// Break answers the IDE's commands while the execution is stopped
+func (s *Session) Break(d *vm.Debugger, stop *vm.DebugStop) (vm.DebugAction, error) {
+	if s.status == "starting" {
+		// The IDE sets the breakpoints, then runs or steps into the script
+		action, err := s.serve(stop)
+		if err != nil || s.closed || (action != vm.DebugStepInto && !s.lineBreakpointHit(stop)) {
+			return action, err
+		}
+	} else if !s.shouldStop(stop) {
+		return s.action, nil
+	}
+
+	s.status = "break"
+	if s.pending != nil {
+		s.respond(newResponse(s.pending).attr("status", "break").attr("reason", "ok"),
+			&message{Filename: fileURI(stop.File), Lineno: stop.Line})
+		s.pending = nil
+	}
+	return s.serve(stop)
+}
+
+// shouldStop reports whether the execution stops: at the end of a step,
+// or at a breakpoint that is enabled and whose conditions hold
+func (s *Session) shouldStop(stop *vm.DebugStop) bool {
+	hit := stop.Breakpoint != nil && s.accept(stop.Breakpoint, stop)
+	return hit || stop.Step
+}
+
+// lineBreakpointHit reports whether a breakpoint, set before the script
+// ran, stops it at its first line
+func (s *Session) lineBreakpointHit(stop *vm.DebugStop) bool {
+	for _, bp := range s.debugger.Breakpoints() {
+		if bp.Matches(stop) {
+			bp.Hits++
+			if s.accept(bp, stop) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// accept reports whether a breakpoint the execution reached stops it,
+// counting the hit only then
+func (s *Session) accept(bp *vm.Breakpoint, stop *vm.DebugStop) bool {
+	b := s.breakpoints[bp.ID]
+	if b == nil {
+		return true
+	}
+	if !b.enabled || (b.condition != "" && !s.conditionHolds(b.condition)) {
+		bp.Hits--
+		return false
+	}
+	switch {
+	case b.hitValue <= 0:
+	case b.hitCondition == "==" && bp.Hits != b.hitValue:
+		return false
+	case b.hitCondition == "%" && bp.Hits%b.hitValue != 0:
+		return false
+	case (b.hitCondition == "" || b.hitCondition == ">=") && bp.Hits < b.hitValue:
+		return false
+	}
+	if b.temporary {
+		s.debugger.RemoveBreakpoint(bp.ID)
+		delete(s.breakpoints, bp.ID)
+	}
+	return true
+}
+
+// conditionHolds evaluates the condition of a breakpoint where the
+// execution is; a failing condition does not stop it
+func (s *Session) conditionHolds(condition string) bool {
+	value, err := s.debugger.Eval(condition, 0)
+	return err == nil && value.ToBool()
+}
+
+// serve answers commands until one resumes the execution, returning how
+func (s *Session) serve(stop *vm.DebugStop) (vm.DebugAction, error) {
+	for {
+		cmd, err := readCommand(s.reader)
+		if err != nil {
+			// The IDE is gone: the script runs to the end
+			s.close()
+			return vm.DebugDetach, nil
+		}
+
+		switch cmd.name {
+		case "run", "step_into", "step_over", "step_out":
+			s.pending, s.status = cmd, "running"
+			s.action = map[string]vm.DebugAction{
+				"run":       vm.DebugContinue,
+				"step_into": vm.DebugStepInto,
+				"step_over": vm.DebugStepOver,
+				"step_out":  vm.DebugStepOut,
+			}[cmd.name]
+			return s.action, nil
+		case "stop":
+			s.status = "stopped"
+			s.respond(newResponse(cmd).attr("status", "stopped").attr("reason", "ok"))
+			s.close()
+			return vm.DebugContinue, ErrStopped
+		case "detach":
+			s.status = "stopping"
+			s.respond(newResponse(cmd).attr("status", "stopping").attr("reason", "ok"))
+			s.close()
+			return vm.DebugDetach, nil
+		default:
+			s.handle(cmd, stop)
+		}
+	}
+}
+
+// Close ends the session once the script has run, telling the IDE it
+// stopped. The IDE may still inspect the session until it stops or
+// detaches.
+func (s *Session) Close(runErr error) error {
+	if s.closed {
+		return nil
+	}
+	s.debugger.Detach()
+	reason := "ok"
+	if runErr != nil {
+		reason = "error"
+	}
+	s.status = "stopping"
+	if s.pending != nil {
+		s.respond(newResponse(s.pending).attr("status", "stopping").attr("reason", reason))
+		s.pending = nil
+	}
+
+	for !s.closed {
+		cmd, err := readCommand(s.reader)
+		if err != nil {
+			break
+		}
+		switch cmd.name {
+		case "run", "step_into", "step_over", "step_out", "stop", "detach":
+			s.status = "stopped"
+			s.respond(newResponse(cmd).attr("status", "stopped").attr("reason", reason))
+			s.close()
+		default:
+			s.handle(cmd, nil)
+		}
+	}
+	s.close()
+	return nil
+}
+
+// close closes the connection to the IDE
+func (s *Session) close() {
+	if !s.closed {
+		s.closed = true
+		s.conn.Close()
+		s.debugger.Detach()
+	}
+}
+
+// respond writes a response with its child elements
+func (s *Session) respond(r *response, children ...interface{}) {
+	if s.closed {
+		return
+	}
+	r.Children = append(r.Children, children...)
+	if err := writePacket(s.conn, r); err != nil {
+		s.close()
+	}
+}
+
+// fail responds to a command with an error
+func (s *Session) fail(cmd *command, code int, format string, args ...interface{}) {
+	s.respond(newResponse(cmd), &errorElement{Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// ============================================================================
+// Commands
+// ============================================================================
+
+// handle answers a command that does not resume the execution; stop is
+// where the execution is, nil once it ended
+func (s *Session) handle(cmd *command, stop *vm.DebugStop) {
+	if cmd.err != nil {
+		s.fail(cmd, errParse, "parse error in command: %v", cmd.err)
+		return
+	}
+	switch cmd.name {
+	case "status":
+		s.respond(newResponse(cmd).attr("status", s.status).attr("reason", "ok"))
+	case "feature_get":
+		s.featureGet(cmd)
+	case "feature_set":
+		s.featureSet(cmd)
+	case "typemap_get":
+		s.typemapGet(cmd)
+	case "breakpoint_set":
+		s.breakpointSet(cmd, stop)
+	case "breakpoint_get", "breakpoint_update", "breakpoint_remove":
+		s.breakpointCommand(cmd)
+	case "breakpoint_list":
+		r := newResponse(cmd)
+		for _, bp := range s.debugger.Breakpoints() {
+			r.Children = append(r.Children, s.describeBreakpoint(bp))
+		}
+		s.respond(r)
+	case "stack_depth":
+		s.respond(newResponse(cmd).attr("depth", strconv.Itoa(len(s.debugger.Backtrace()))))
+	case "stack_get":
+		s.stackGet(cmd)
+	case "context_names":
+		s.respond(newResponse(cmd), &contextElement{Name: "Locals", ID: 0})
+	case "context_get":
+		s.contextGet(cmd)
+	case "property_get", "property_value":
+		s.propertyGet(cmd)
+	case "property_set":
+		s.propertySet(cmd)
+	case "eval":
+		s.eval(cmd)
+	case "source":
+		s.source(cmd, stop)
+	case "stdout":
+		mode, err := strconv.Atoi(cmd.args["c"])
+		if err != nil || mode < 0 || mode > 2 {
+			s.fail(cmd, errInvalidOptions, "invalid stdout mode %q", cmd.args["c"])
+			return
+		}
+		s.stdout = mode
+		s.respond(newResponse(cmd).attr("success", "1"))
+	case "stderr":
+		s.respond(newResponse(cmd).attr("success", "0"))
+	default:
+		s.fail(cmd, errUnimplemented, "unimplemented command %q", cmd.name)
+	}
+}
+
+// featureGet reports a feature of the engine
+func (s *Session) featureGet(cmd *command) {
+	name := cmd.args["n"]
+	value, ok := s.features[name]
+	if !ok {
+		value, ok = map[string]string{
+			"language_supports_threads": "0",
+			"language_name":             "PHP",
+			"language_version":          s.phpVersion(),
+			"protocol_version":          "1",
+			"supports_async":            "0",
+			"supports_postmortem":       "0",
+			"data_encoding":             "base64",
+			"breakpoint_languages":      "PHP",
+			"breakpoint_types":          "line conditional call",
+		}[name]
+	}
+	r := newResponse(cmd).attr("feature_name", name)
+	if !ok {
+		s.respond(r.attr("supported", "0"))
+		return
+	}
+	r.Value = value
+	s.respond(r.attr("supported", "1"))
+}
+
+// featureSet changes a setting of the session
+func (s *Session) featureSet(cmd *command) {
+	name, value := cmd.args["n"], cmd.args["v"]
+	if _, ok := s.features[name]; !ok {
+		s.respond(newResponse(cmd).attr("feature", name).attr("success", "0"))
+		return
+	}
+	if strings.HasPrefix(name, "max_") {
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			s.fail(cmd, errInvalidOptions, "invalid value %q for %s", value, name)
+			return
+		}
+	}
+	s.features[name] = value
+	s.respond(newResponse(cmd).attr("feature", name).attr("success", "1"))
+}
+
+// feature returns a numeric setting
+func (s *Session) feature(name string) int {
+	n, _ := strconv.Atoi(s.features[name])
+	return n
+}
+
+// typemapGet maps the PHP types to XML Schema types
+func (s *Session) typemapGet(cmd *command) {
+	r := newResponse(cmd).
+		attr("xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance").
+		attr("xmlns:xsd", "http://www.w3.org/2001/XMLSchema")
+	for _, m := range []typeMap{
+		{Name: "bool", Type: "bool", XSIType: "xsd:boolean"},
+		{Name: "int", Type: "int", XSIType: "xsd:decimal"},
+		{Name: "float", Type: "float", XSIType: "xsd:double"},
+		{Name: "string", Type: "string", XSIType: "xsd:string"},
+		{Name: "null", Type: "null"},
+		{Name: "array", Type: "hash"},
+		{Name: "object", Type: "object"},
+		{Name: "resource", Type: "resource"},
+	} {
+		r.Children = append(r.Children, m)
+	}
+	s.respond(r)
+}
+
+// breakpointSet adds a line, conditional or call breakpoint
+func (s *Session) breakpointSet(cmd *command, stop *vm.DebugStop) {
+	b := &breakpoint{
+		kind:         cmd.args["t"],
+		enabled:      cmd.args["s"] != "disabled",
+		temporary:    cmd.args["r"] == "1",
+		condition:    cmd.data,
+		hitCondition: cmd.args["o"],
+	}
+	if value := cmd.args["h"]; value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			s.fail(cmd, errBreakpointInvalid, "invalid hit value %q", value)
+			return
+		}
+		b.hitValue = n
+	}
+
+	bp := &vm.Breakpoint{}
+	switch b.kind {
+	case "line", "conditional":
+		line, err := strconv.Atoi(cmd.args["n"])
+		if err != nil || line <= 0 {
+			s.fail(cmd, errBreakpointInvalid, "invalid line number %q", cmd.args["n"])
+			return
+		}
+		bp.Line = line
+		bp.File = filePath(cmd.args["f"])
+		if bp.File == "" && stop != nil {
+			bp.File = stop.File
+		}
+		if bp.File == "" {
+			s.fail(cmd, errBreakpointInvalid, "no file given for the breakpoint")
+			return
+		}
+	case "call":
+		bp.Function = cmd.args["m"]
+		if class := cmd.args["a"]; class != "" {
+			bp.Function = class + "::" + bp.Function
+		}
+		if bp.Function == "" {
+			s.fail(cmd, errBreakpointInvalid, "no function given for the breakpoint")
+			return
+		}
+	default:
+		s.fail(cmd, errBreakpointType, "breakpoint type %q is not supported", b.kind)
+		return
+	}
+
+	b.bp = s.debugger.AddBreakpoint(bp)
+	s.breakpoints[bp.ID] = b
+	s.respond(newResponse(cmd).attr("id", strconv.Itoa(bp.ID)).attr("state", b.state()))
+}
+
+// breakpointCommand gets, updates or removes the breakpoint given by -d
+func (s *Session) breakpointCommand(cmd *command) {
+	id, _ := strconv.Atoi(cmd.args["d"])
+	b, ok := s.breakpoints[id]
+	if !ok {
+		s.fail(cmd, errNoBreakpoint, "no breakpoint with ID %q", cmd.args["d"])
+		return
+	}
+
+	switch cmd.name {
+	case "breakpoint_remove":
+		element := s.describeBreakpoint(b.bp)
+		s.debugger.RemoveBreakpoint(id)
+		delete(s.breakpoints, id)
+		s.respond(newResponse(cmd), element)
+		return
+	case "breakpoint_update":
+		if state, ok := cmd.args["s"]; ok {
+			b.enabled = state != "disabled"
+		}
+		if value, ok := cmd.args["n"]; ok {
+			line, err := strconv.Atoi(value)
+			if err != nil || line <= 0 {
+				s.fail(cmd, errInvalidOptions, "invalid line number %q", value)
+				return
+			}
+			b.bp.Line = line
+		}
+		if value, ok := cmd.args["h"]; ok {
+			b.hitValue, _ = strconv.Atoi(value)
+		}
+		if value, ok := cmd.args["o"]; ok {
+			b.hitCondition = value
+		}
+	}
+	s.respond(newResponse(cmd), s.describeBreakpoint(b.bp))
+}
+
+// describeBreakpoint returns the element describing a breakpoint
+func (s *Session) describeBreakpoint(bp *vm.Breakpoint) *breakpointElement {
+	b := s.breakpoints[bp.ID]
+	element := &breakpointElement{
+		ID:           bp.ID,
+		Type:         b.kind,
+		State:        b.state(),
+		Lineno:       bp.Line,
+		Function:     bp.Function,
+		HitCount:     bp.Hits,
+		HitValue:     b.hitValue,
+		HitCondition: b.hitCondition,
+		Expression:   b.condition,
+	}
+	if element.HitCondition == "" {
+		element.HitCondition = ">="
+	}
+	if bp.File != "" {
+		element.Filename = fileURI(bp.File)
+	}
+	return element
+}
+
+// state names whether a breakpoint is enabled
+func (b *breakpoint) state() string {
+	if b.enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// stackGet describes the frames of the call stack, or the one given by -d
+func (s *Session) stackGet(cmd *command) {
+	frames := s.debugger.Backtrace()
+	r := newResponse(cmd)
+	for level, frame := range frames {
+		if value, ok := cmd.args["d"]; ok && value != strconv.Itoa(level) {
+			continue
+		}
+		where := frame.Function
+		if level == len(frames)-1 {
+			where = "{main}"
+		}
+		r.Children = append(r.Children, &stackElement{
+			Where:    where,
+			Level:    level,
+			Type:     "file",
+			Filename: fileURI(frame.File),
+			Lineno:   frame.Line,
+		})
+	}
+	s.respond(r)
+}
+
+// depth returns the stack depth given by -d, 0 by default
+func (s *Session) depth(cmd *command) (int, bool) {
+	value, ok := cmd.args["d"]
+	if !ok {
+		return 0, true
+	}
+	depth, err := strconv.Atoi(value)
+	if err != nil || depth < 0 || depth >= len(s.debugger.Backtrace()) {
+		s.fail(cmd, errStackDepth, "stack depth invalid")
+		return 0, false
+	}
+	return depth, true
+}
+
+// contextGet lists the variables of a frame: $this, then its locals
+func (s *Session) contextGet(cmd *command) {
+	if context := cmd.args["c"]; context != "" && context != "0" {
+		s.fail(cmd, errContext, "no such context %q", context)
+		return
+	}
+	depth, ok := s.depth(cmd)
+	if !ok {
+		return
+	}
+	locals, err := s.debugger.Locals(depth)
+	if err != nil {
+		s.fail(cmd, errStackDepth, "%v", err)
+		return
+	}
+
+	r := newResponse(cmd).attr("context", "0")
+	maxDepth := s.feature("max_depth")
+	if this, _ := s.debugger.This(depth); this != nil {
+		r.Children = append(r.Children, s.property(types.NewObject(this), "$this", "$this", maxDepth, 0))
+	}
+	sort.SliceStable(locals, func(i, j int) bool { return locals[i].Name < locals[j].Name })
+	for _, local := range locals {
+		name := "$" + local.Name
+		r.Children = append(r.Children, s.property(local.Value, name, name, maxDepth, 0))
+	}
+	s.respond(r)
+}
+
+// propertyGet describes a variable given by its full name, with the page
+// of its children given by -p; property_value returns its value alone
+func (s *Session) propertyGet(cmd *command) {
+	depth, ok := s.depth(cmd)
+	if !ok {
+		return
+	}
+	name := cmd.args["n"]
+	value, err := s.lookup(name, depth)
+	if err != nil {
+		s.fail(cmd, errNoProperty, "can not get property %q: %v", name, err)
+		return
+	}
+	if maxData, ok := cmd.args["m"]; ok {
+		defer func(previous string) { s.features["max_data"] = previous }(s.features["max_data"])
+		s.features["max_data"] = maxData
+	}
+	page, _ := strconv.Atoi(cmd.args["p"])
+	prop := s.property(value, name, name, s.feature("max_depth"), page)
+
+	if cmd.name == "property_value" {
+		r := newResponse(cmd).attr("type", prop.Type)
+		if prop.Size != "" {
+			r.attr("size", prop.Size)
+		}
+		if prop.Encoding != "" {
+			r.attr("encoding", prop.Encoding)
+		}
+		r.Value = prop.Value
+		s.respond(r)
+		return
+	}
+	s.respond(newResponse(cmd), prop)
+}
+
+// propertySet assigns the value in the data, a PHP expression, to a
+// variable given by its full name
+func (s *Session) propertySet(cmd *command) {
+	depth, ok := s.depth(cmd)
+	if !ok {
+		return
+	}
+	name := cmd.args["n"]
+	if name == "" {
+		s.fail(cmd, errInvalidOptions, "no property given")
+		return
+	}
+	_, err := s.debugger.Eval(name+" = "+cmd.data, depth)
+	success := "1"
+	if err != nil {
+		success = "0"
+	}
+	s.respond(newResponse(cmd).attr("success", success))
+}
+
+// eval evaluates the expression in the data in the innermost frame
+func (s *Session) eval(cmd *command) {
+	value, err := s.debugger.Eval(cmd.data, 0)
+	if err != nil {
+		s.fail(cmd, errEvaluation, "error evaluating code: %v", err)
+		return
+	}
+	s.respond(newResponse(cmd), s.property(value, "", "", s.feature("max_depth"), 0))
+}
+
+// source returns lines -b to -e of a file, the one executing by default
+func (s *Session) source(cmd *command, stop *vm.DebugStop) {
+	path := filePath(cmd.args["f"])
+	if path == "" && stop != nil {
+		path = stop.File
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		s.fail(cmd, errFileOpen, "can not open file %q", path)
+		return
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	begin, end := 1, len(lines)
+	if n, err := strconv.Atoi(cmd.args["b"]); err == nil && n > 0 {
+		begin = n
+	}
+	if n, err := strconv.Atoi(cmd.args["e"]); err == nil && n < end {
+		end = n
+	}
+	text := ""
+	if begin <= end {
+		text = strings.Join(lines[begin-1:end], "")
+	}
+	r := newResponse(cmd).attr("success", "1").attr("encoding", "base64")
+	r.Value = base64.StdEncoding.EncodeToString([]byte(text))
+	s.respond(r)
+}
+
+// phpVersion returns the PHP version the VM implements
+func (s *Session) phpVersion() string {
+	if version, ok := s.machine.LookupConstant("PHP_VERSION"); ok {
+		return version.ToString()
+	}
+	return ""
+}
+
+// ============================================================================
+// Output
+// ============================================================================
+
+// Output returns a writer for the output of the script, which writes to w
+// and copies to the IDE or redirects there as the stdout command asks
+func (s *Session) Output(w io.Writer) io.Writer {
+	return &outputWriter{session: s, w: w}
+}
+
+// outputWriter writes the output of the script (see Session.Output)
+type outputWriter struct {
+	session *Session
+	w       io.Writer
+}
+
+func (o *outputWriter) Write(p []byte) (int, error) {
+	s := o.session
+	if s.stdout != 0 && !s.closed {
+		packet := &stream{Xmlns: namespace, Type: "stdout", Encoding: "base64", Data: base64.StdEncoding.EncodeToString(p)}
+		if err := writePacket(s.conn, packet); err != nil {
+			s.close()
+		}
+	}
+	if s.stdout == 2 && !s.closed {
+		return len(p), nil
+	}
+	return o.w.Write(p)
+}
+
+// ============================================================================
+// File URIs
+// ============================================================================
+
+// fileURI returns the file:// URI of a path
+func fileURI(path string) string {
+	if strings.Contains(path, "://") {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// filePath returns the path of a file:// URI
+func filePath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
@@ -0,0 +1,358 @@
+package dbgp
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/compiler"
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// node is a parsed DBGp message
+type node struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []node     `xml:",any"`
+	Text     string     `xml:",chardata"`
+}
+
+// attr returns the value of an attribute
+func (n node) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// child returns the first child element with a name
+func (n node) child(name string) node {
+	for _, c := range n.Children {
+		if c.XMLName.Local == name {
+			return c
+		}
+	}
+	return node{}
+}
+
+// ide plays the IDE side of a session
+type ide struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	id     int
+}
+
+// receive reads a message from the engine
+func (c *ide) receive() node {
+	c.t.Helper()
+	length, err := c.reader.ReadString(0)
+	if err != nil {
+		c.t.Fatalf("reading the message length: %v", err)
+	}
+	body, err := c.reader.ReadString(0)
+	if err != nil {
+		c.t.Fatalf("reading the message: %v", err)
+	}
+	body = strings.TrimSuffix(body, "\x00")
+	if n, _ := strconv.Atoi(strings.TrimSuffix(length, "\x00")); n != len(body) {
+		c.t.Errorf("Message length %d, want %d", n, len(body))
+	}
+	var message node
+	decoder := xml.NewDecoder(strings.NewReader(body))
+	// The messages are ASCII: their strings are encoded in base64
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	if err := decoder.Decode(&message); err != nil {
+		c.t.Fatalf("invalid message %q: %v", body, err)
+	}
+	return message
+}
+
+// send sends a command with the next transaction ID and returns its response
+func (c *ide) send(command string, args ...string) node {
+	c.t.Helper()
+	c.id++
+	line := command + " -i " + strconv.Itoa(c.id)
+	for _, arg := range args {
+		line += " " + arg
+	}
+	if _, err := c.conn.Write([]byte(line + "\x00")); err != nil {
+		c.t.Fatalf("sending %q: %v", line, err)
+	}
+	response := c.receive()
+	if response.attr("transaction_id") != strconv.Itoa(c.id) || response.attr("command") != command {
+		c.t.Errorf("%s: unexpected response %+v", line, response)
+	}
+	return response
+}
+
+// debugScript runs a script under a session driven by ide, returning the
+// output and the error of the script
+func debugScript(t *testing.T, source string, drive func(c *ide, path string)) (string, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.php")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	compile := compiler.ScriptCompiler(compiler.OptimizeNone)
+	script := &vm.Script{Path: path, Constants: testConstants, Variables: testVariables, Instructions: testInstructions}
+
+	engineConn, ideConn := net.Pipe()
+	machine := vm.New()
+	machine.SetScriptCompiler(compile)
+	machine.SetScriptPath(path)
+	done := make(chan error, 1)
+	go func() {
+		session, err := Attach(engineConn, machine, path, "TEST")
+		if err != nil {
+			done <- err
+			return
+		}
+		runErr := machine.ExecuteScript(script)
+		session.Close(runErr)
+		done <- runErr
+	}()
+
+	c := &ide{t: t, conn: ideConn, reader: bufio.NewReader(ideConn)}
+	init := c.receive()
+	if init.XMLName.Local != "init" || init.attr("idekey") != "TEST" || init.attr("fileuri") != fileURI(path) || init.attr("language") != "PHP" {
+		t.Errorf("Unexpected init packet %+v", init)
+	}
+	drive(c, path)
+	ideConn.Close()
+	runErr := <-done
+	return machine.GetOutput(), runErr
+}
+
+func encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func decode(t *testing.T, s string) string {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid base64 %q", s)
+	}
+	return string(data)
+}
+
+// testInstructions is the op array of testScript
+var (
+	testVariables    = []string{"t", "a", "s", "e"}
+	testConstants    = []interface{}{int64(0), int64(1), "text", "", int64(2), "out"}
+	testInstructions = vm.Instructions{
+		{Opcode: vm.OpAssign, Op2: vm.Operand{Type: vm.OpConst, Value: 0}, Result: vm.Operand{Type: vm.OpCV, Value: 0}, Lineno: 2},
+		{Opcode: vm.OpAssign, Op2: vm.Operand{Type: vm.OpConst, Value: 1}, Result: vm.Operand{Type: vm.OpCV, Value: 1}, Lineno: 3},
+		{Opcode: vm.OpAssign, Op2: vm.Operand{Type: vm.OpConst, Value: 2}, Result: vm.Operand{Type: vm.OpCV, Value: 2}, Lineno: 4},
+		{Opcode: vm.OpAssign, Op2: vm.Operand{Type: vm.OpConst, Value: 3}, Result: vm.Operand{Type: vm.OpCV, Value: 3}, Lineno: 5},
+		{Opcode: vm.OpAssign, Op2: vm.Operand{Type: vm.OpConst, Value: 4}, Result: vm.Operand{Type: vm.OpCV, Value: 1}, Lineno: 6},
+		{Opcode: vm.OpEcho, Op1: vm.Operand{Type: vm.OpConst, Value: 5}, Lineno: 7},
+	}
+)
+
+const testScript = `<?php
+$t = 0;
+$a = 1;
+$s = "text";
+$e = "";
+$a = 2;
+echo "out";
+`
+
+func TestSession_BreakpointsAndInspection(t *testing.T) {
+	output, err := debugScript(t, testScript, func(c *ide, path string) {
+		if r := c.send("feature_set", "-n", "max_data", "-v", "2"); r.attr("success") != "1" {
+			t.Errorf("feature_set failed: %+v", r)
+		}
+		if r := c.send("feature_get", "-n", "language_name"); r.attr("supported") != "1" || r.Text != "PHP" {
+			t.Errorf("Unexpected feature_get response %+v", r)
+		}
+		r := c.send("breakpoint_set", "-t", "line", "-f", fileURI(path), "-n", "6")
+		if r.attr("id") == "" || r.attr("state") != "enabled" {
+			t.Fatalf("Unexpected breakpoint_set response %+v", r)
+		}
+
+		r = c.send("run")
+		if r.attr("status") != "break" || r.child("message").attr("lineno") != "6" {
+			t.Fatalf("Expected a break on line 6, got %+v", r)
+		}
+
+		r = c.send("context_get", "-d", "0")
+		vars := map[string]node{}
+		for _, prop := range r.Children {
+			vars[prop.attr("name")] = prop
+		}
+		if a := vars["$a"]; a.attr("type") != "int" || a.Text != "1" {
+			t.Errorf("Expected $a = 1, got %+v", a)
+		}
+		if s := vars["$s"]; s.attr("type") != "string" || s.attr("size") != "4" || decode(t, s.Text) != "te" {
+			t.Errorf(`Expected $s = "text" cut to max_data, got %+v`, s)
+		}
+
+		if r := c.send("property_value", "-n", "$s", "-m", "0"); decode(t, r.Text) != "text" {
+			t.Errorf("Expected the whole of $s, got %+v", r)
+		}
+		if r := c.send("eval", "--", encode("$a")); r.child("property").Text != "1" {
+			t.Errorf("Expected $a = 1, got %+v", r)
+		}
+		if r := c.send("eval", "--", encode("$a +")); r.child("error").attr("code") != "206" {
+			t.Errorf("Expected an evaluation error, got %+v", r)
+		}
+		if r := c.send("property_set", "-n", "$a", "--", encode("41")); r.attr("success") != "1" {
+			t.Errorf("property_set failed: %+v", r)
+		}
+
+		r = c.send("step_over")
+		if r.attr("status") != "break" || r.child("message").attr("lineno") != "7" {
+			t.Fatalf("Expected a break on line 7, got %+v", r)
+		}
+		if r := c.send("property_get", "-n", "$a"); r.child("property").Text != "2" {
+			t.Errorf("Expected $a = 2, got %+v", r)
+		}
+		r = c.send("stack_get")
+		if frame := r.child("stack"); frame.attr("where") != "{main}" || frame.attr("lineno") != "7" || frame.attr("filename") != fileURI(path) {
+			t.Errorf("Unexpected stack %+v", r)
+		}
+		if r := c.send("source", "-b", "3", "-e", "4"); decode(t, r.Text) != "$a = 1;\n$s = \"text\";\n" {
+			t.Errorf("Unexpected source %q", decode(t, r.Text))
+		}
+		if r := c.send("breakpoint_list"); len(r.Children) != 1 || r.Children[0].attr("hit_count") != "1" {
+			t.Errorf("Unexpected breakpoint list %+v", r)
+		}
+		if r := c.send("no_such_command"); r.child("error").attr("code") != "4" {
+			t.Errorf("Expected an unimplemented command error, got %+v", r)
+		}
+
+		if r := c.send("run"); r.attr("status") != "stopping" {
+			t.Errorf("Expected the script to end, got %+v", r)
+		}
+		if r := c.send("stop"); r.attr("status") != "stopped" {
+			t.Errorf("Expected the session to stop, got %+v", r)
+		}
+	})
+	if err != nil || output != "out" {
+		t.Errorf("Expected the script to output out, got %q (%v)", output, err)
+	}
+}
+
+func TestSession_StepIntoAndStop(t *testing.T) {
+	_, err := debugScript(t, testScript, func(c *ide, path string) {
+		r := c.send("step_into")
+		if r.attr("status") != "break" || r.child("message").attr("lineno") != "2" {
+			t.Fatalf("Expected a break on the first line, got %+v", r)
+		}
+		if r := c.send("status"); r.attr("status") != "break" {
+			t.Errorf("Unexpected status %+v", r)
+		}
+		if r := c.send("stop"); r.attr("status") != "stopped" {
+			t.Errorf("Expected the session to stop, got %+v", r)
+		}
+	})
+	if !errors.Is(err, ErrStopped) {
+		t.Errorf("Expected the script to be stopped, got %v", err)
+	}
+}
+
+func TestSession_ConditionalBreakpoints(t *testing.T) {
+	_, err := debugScript(t, testScript, func(c *ide, path string) {
+		c.send("breakpoint_set", "-t", "line", "-f", fileURI(path), "-n", "5", "-s", "disabled")
+		c.send("breakpoint_set", "-t", "conditional", "-f", fileURI(path), "-n", "6", "--", encode("$e"))
+		r := c.send("breakpoint_set", "-t", "conditional", "-f", fileURI(path), "-n", "7", "--", encode("$s"))
+		id := r.attr("id")
+
+		r = c.send("run")
+		if r.attr("status") != "break" || r.child("message").attr("lineno") != "7" {
+			t.Fatalf("Expected a break on line 7 only, got %+v", r)
+		}
+		if r := c.send("breakpoint_get", "-d", id); r.child("breakpoint").attr("hit_count") != "1" {
+			t.Errorf("Unexpected breakpoint %+v", r)
+		}
+		if r := c.send("breakpoint_remove", "-d", id); r.child("breakpoint").attr("id") != id {
+			t.Errorf("Unexpected breakpoint_remove response %+v", r)
+		}
+		if r := c.send("breakpoint_get", "-d", id); r.child("error").attr("code") != "205" {
+			t.Errorf("Expected the breakpoint to be removed, got %+v", r)
+		}
+		c.send("run")
+		c.send("stop")
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSession_Properties(t *testing.T) {
+	s := &Session{features: map[string]string{"max_children": "2", "max_data": "0"}}
+	arr := types.NewEmptyArray()
+	arr.Set(types.NewInt(0), types.NewInt(10))
+	arr.Set(types.NewString(`k"$`), types.NewBool(true))
+	arr.Set(types.NewString("f"), types.NewFloat(1.5))
+
+	prop := s.property(types.NewArray(arr), "$a", "$a", 1, 0)
+	if prop.Type != "array" || prop.NumChildren != "3" || prop.PageSize != "2" || len(prop.Properties) != 2 {
+		t.Fatalf("Unexpected property %+v", prop)
+	}
+	if child := prop.Properties[1]; child.Fullname != `$a["k\"\$"]` || child.Type != "bool" || child.Value != "1" {
+		t.Errorf("Unexpected child %+v", child)
+	}
+	page := s.property(types.NewArray(arr), "$a", "$a", 1, 1)
+	if len(page.Properties) != 1 || page.Properties[0].Fullname != `$a["f"]` || page.Properties[0].Value != "1.5" {
+		t.Errorf("Unexpected second page %+v", page.Properties)
+	}
+	if nested := s.property(types.NewArray(arr), "$a", "$a", 0, 0); len(nested.Properties) != 0 || nested.Children != "1" {
+		t.Errorf("Expected no children at depth 0, got %+v", nested)
+	}
+
+	key, rest, ok := parseKey(`"k\"\$"]->x`)
+	if !ok || key.ToString() != `k"$` || rest != "->x" {
+		t.Errorf("parseKey: got %v %q %v", key, rest, ok)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	cmd, err := parseCommand(`property_get -i 7 -n "$a[\"b c\"]" -d 1 -- ` + encode("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.name != "property_get" || cmd.transaction != "7" || cmd.args["n"] != `$a["b c"]` || cmd.args["d"] != "1" || cmd.data != "data" {
+		t.Errorf("Unexpected command %+v", cmd)
+	}
+	for _, line := range []string{"", `run -i "1`, "run i 1", "eval -i 1 -- !!"} {
+		if _, err := parseCommand(line); err == nil {
+			t.Errorf("Expected %q not to parse", line)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		env     map[string]string
+		enabled bool
+		config  Config
+	}{
+		{map[string]string{}, false, Config{Host: "localhost", Port: 9003}},
+		{map[string]string{"XDEBUG_SESSION": "PHPSTORM"}, true, Config{Host: "localhost", Port: 9003, IDEKey: "PHPSTORM"}},
+		{map[string]string{"XDEBUG_SESSION": "1", "XDEBUG_CONFIG": "client_host=10.0.0.2 client_port=9000 idekey=vsc"}, true, Config{Host: "10.0.0.2", Port: 9000, IDEKey: "vsc"}},
+		{map[string]string{"XDEBUG_MODE": "develop,debug", "XDEBUG_CONFIG": "start_with_request=yes remote_port=9100"}, true, Config{Host: "localhost", Port: 9100}},
+		{map[string]string{"XDEBUG_MODE": "debug"}, false, Config{Host: "localhost", Port: 9003}},
+		{map[string]string{"XDEBUG_MODE": "off", "XDEBUG_SESSION": "x"}, false, Config{Host: "localhost", Port: 9003, IDEKey: "x"}},
+	}
+	for _, tt := range tests {
+		config, enabled := ConfigFromEnv(func(name string) string { return tt.env[name] })
+		if enabled != tt.enabled || config != tt.config {
+			t.Errorf("%v: got %+v %v, want %+v %v", tt.env, config, enabled, tt.config, tt.enabled)
+		}
+	}
+}
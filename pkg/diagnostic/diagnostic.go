@@ -0,0 +1,212 @@
+// Package diagnostic gives the errors of php-go - syntax errors, compile
+// errors and the errors of running scripts - one structured form, with a
+// code, a severity and a source range, for tools to consume.
+package diagnostic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/krizos/php-go/pkg/lexer"
+	"github.com/krizos/php-go/pkg/parser"
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// Severity is how serious a diagnostic is
+type Severity string
+
+const (
+	SeverityError      Severity = "error"
+	SeverityWarning    Severity = "warning"
+	SeverityNotice     Severity = "notice"
+	SeverityDeprecated Severity = "deprecated"
+)
+
+// Codes of the diagnostics not raised at run time. Those raised at run
+// time have the name of their PHP error level, such as E_WARNING.
+const (
+	CodeSyntaxError       = "syntax-error"       // Unexpected token
+	CodeExpectedToken     = "expected-token"     // A given token was expected
+	CodeCompileError      = "compile-error"      // Valid syntax the compiler rejects
+	CodeUncaughtException = "uncaught-exception" // Exception nothing caught
+	CodeRuntimeError      = "runtime-error"      // Engine error without a PHP level
+)
+
+// Position is a position in a file. Lines and columns count from 1; a
+// zero column means only the line is known.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Range is the span of source a diagnostic is about, End excluded
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Note is information related to a diagnostic, possibly at another place
+type Note struct {
+	Message string `json:"message"`
+	File    string `json:"file,omitempty"`
+	Range   *Range `json:"range,omitempty"`
+}
+
+// Diagnostic is an error or warning about a file
+type Diagnostic struct {
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	File     string   `json:"file,omitempty"`
+	Range    *Range   `json:"range,omitempty"` // nil if the position is unknown
+	Notes    []Note   `json:"notes,omitempty"`
+}
+
+// String formats a diagnostic on one line as "file:line:column: severity:
+// message [code]"
+func (d Diagnostic) String() string {
+	location := d.File
+	if d.Range != nil {
+		location += fmt.Sprintf(":%d", d.Range.Start.Line)
+		if d.Range.Start.Column > 0 {
+			location += fmt.Sprintf(":%d", d.Range.Start.Column)
+		}
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", location, d.Severity, d.Message, d.Code)
+}
+
+// lineRange is the range of a whole line, or nil for an unknown line
+func lineRange(line int) *Range {
+	if line <= 0 {
+		return nil
+	}
+	return &Range{Start: Position{Line: line}, End: Position{Line: line}}
+}
+
+// FromSyntaxError converts a syntax error of the parser. The range spans
+// the offending token, and a note lists the tokens expected instead.
+func FromSyntaxError(d *parser.Diagnostic) Diagnostic {
+	length := len(d.Token.Literal)
+	if length == 0 || d.Token.Type == lexer.EOF {
+		length = 1
+	}
+	diagnostic := Diagnostic{
+		Code:     CodeSyntaxError,
+		Severity: SeverityError,
+		Message:  d.Message,
+		File:     d.Pos.Filename,
+		Range: &Range{
+			Start: Position{Line: d.Pos.Line, Column: d.Pos.Column},
+			End:   Position{Line: d.Pos.Line, Column: d.Pos.Column + length},
+		},
+	}
+	if len(d.Expected) > 0 {
+		diagnostic.Code = CodeExpectedToken
+		expected := "expected "
+		for i, t := range d.Expected {
+			if i > 0 {
+				expected += " or "
+			}
+			expected += t.String()
+		}
+		diagnostic.Notes = []Note{{Message: expected}}
+	}
+	return diagnostic
+}
+
+// FromParser converts the syntax errors of a parse
+func FromParser(p *parser.Parser) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, d := range p.Diagnostics() {
+		diagnostics = append(diagnostics, FromSyntaxError(d))
+	}
+	return diagnostics
+}
+
+// FromCompileError converts an error of the compiler, which knows the file
+// but not the position
+func FromCompileError(file string, err error) Diagnostic {
+	return Diagnostic{Code: CodeCompileError, Severity: SeverityError, Message: err.Error(), File: file}
+}
+
+// FromRuntimeError converts an error reported by the VM, as passed to a
+// vm.ErrorListener
+func FromRuntimeError(level runtime.ErrorType, message, file string, line int) Diagnostic {
+	return Diagnostic{
+		Code:     levelName(level),
+		Severity: levelSeverity(level),
+		Message:  message,
+		File:     file,
+		Range:    lineRange(line),
+	}
+}
+
+// FromError converts the error a script ended on: a fatal error, an
+// uncaught exception, whose previous exceptions become notes, or an
+// engine error
+func FromError(file string, err error) Diagnostic {
+	var fatal *vm.FatalError
+	var thrown *vm.ThrowableError
+	switch {
+	case errors.As(err, &fatal):
+		return FromRuntimeError(fatal.Level, fatal.Message, fatal.File, fatal.Line)
+	case errors.As(err, &thrown):
+		exceptionFile, line := thrown.Location()
+		d := Diagnostic{
+			Code:     CodeUncaughtException,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("Uncaught %s: %s", thrown.Class(), thrown.Message()),
+			File:     exceptionFile,
+			Range:    lineRange(line),
+		}
+		for previous := thrown.Previous(); previous != nil; previous = previous.Previous() {
+			previousFile, line := previous.Location()
+			d.Notes = append(d.Notes, Note{
+				Message: fmt.Sprintf("previous %s: %s", previous.Class(), previous.Message()),
+				File:    previousFile,
+				Range:   lineRange(line),
+			})
+		}
+		return d
+	}
+	return Diagnostic{Code: CodeRuntimeError, Severity: SeverityError, Message: err.Error(), File: file}
+}
+
+// levelName returns the name of the E_* constant of an error level
+func levelName(level runtime.ErrorType) string {
+	for name, value := range runtime.ErrorConstants {
+		if value == level && name != "E_ALL" {
+			return name
+		}
+	}
+	return CodeRuntimeError
+}
+
+// levelSeverity maps an error level to a severity
+func levelSeverity(level runtime.ErrorType) Severity {
+	switch level {
+	case runtime.E_WARNING, runtime.E_CORE_WARNING, runtime.E_COMPILE_WARNING, runtime.E_USER_WARNING:
+		return SeverityWarning
+	case runtime.E_NOTICE, runtime.E_USER_NOTICE, runtime.E_STRICT:
+		return SeverityNotice
+	case runtime.E_DEPRECATED, runtime.E_USER_DEPRECATED:
+		return SeverityDeprecated
+	}
+	return SeverityError
+}
+
+// WriteJSON writes diagnostics as a JSON document: an object whose
+// "diagnostics" member lists them, empty if there are none
+func WriteJSON(w io.Writer, diagnostics []Diagnostic) error {
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	}{diagnostics})
+}
@@ -0,0 +1,91 @@
+package diagnostic
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/lexer"
+	"github.com/krizos/php-go/pkg/parser"
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+func TestFromParser(t *testing.T) {
+	p := parser.New(lexer.New("<?php\n$a = (1;\n$b = 2 +;\n", "test.php"))
+	p.ParseProgram()
+
+	diagnostics := FromParser(p)
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %v", diagnostics)
+	}
+	expected := diagnostics[0]
+	if expected.Code != CodeExpectedToken || expected.Severity != SeverityError || expected.File != "test.php" {
+		t.Errorf("Unexpected diagnostic %+v", expected)
+	}
+	if r := expected.Range; r == nil || r.Start != (Position{Line: 2, Column: 8}) || r.End != (Position{Line: 2, Column: 9}) {
+		t.Errorf("Expected the range of the ';' at 2:8, got %+v", r)
+	}
+	if len(expected.Notes) != 1 || expected.Notes[0].Message != "expected )" {
+		t.Errorf("Expected a note listing ')', got %+v", expected.Notes)
+	}
+	if diagnostics[1].Code != CodeSyntaxError || diagnostics[1].Range.Start.Line != 3 {
+		t.Errorf("Unexpected diagnostic %s", diagnostics[1])
+	}
+	if got := diagnostics[1].String(); got != "test.php:3:9: error: no prefix parse function for ; [syntax-error]" {
+		t.Errorf("Unexpected string %q", got)
+	}
+}
+
+func TestFromRuntimeError(t *testing.T) {
+	d := FromRuntimeError(runtime.E_DEPRECATED, "old", "a.php", 4)
+	if d.Code != "E_DEPRECATED" || d.Severity != SeverityDeprecated || d.Range.Start.Line != 4 || d.Range.Start.Column != 0 {
+		t.Errorf("Unexpected diagnostic %+v", d)
+	}
+	if got := d.String(); got != "a.php:4: deprecated: old [E_DEPRECATED]" {
+		t.Errorf("Unexpected string %q", got)
+	}
+}
+
+func TestFromError(t *testing.T) {
+	fatal := FromError("a.php", &vm.FatalError{Level: runtime.E_USER_ERROR, Message: "stop", File: "b.php", Line: 7})
+	if fatal.Code != "E_USER_ERROR" || fatal.Severity != SeverityError || fatal.File != "b.php" || fatal.Range.Start.Line != 7 {
+		t.Errorf("Unexpected diagnostic for a fatal error %+v", fatal)
+	}
+
+	uncaught := FromError("a.php", vm.New().ThrowError("RuntimeException", "boom"))
+	if uncaught.Code != CodeUncaughtException || uncaught.Message != "Uncaught RuntimeException: boom" {
+		t.Errorf("Unexpected diagnostic for an exception %+v", uncaught)
+	}
+
+	engine := FromError("a.php", errors.New("stack overflow"))
+	if engine.Code != CodeRuntimeError || engine.File != "a.php" || engine.Range != nil {
+		t.Errorf("Unexpected diagnostic for an engine error %+v", engine)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "{\n  \"diagnostics\": []\n}\n" {
+		t.Errorf("Expected an empty list, got %q", got)
+	}
+
+	buf.Reset()
+	want := []Diagnostic{FromRuntimeError(runtime.E_WARNING, "careful", "a.php", 2)}
+	if err := WriteJSON(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	var document struct {
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+		t.Fatalf("invalid JSON %s: %v", buf.String(), err)
+	}
+	if len(document.Diagnostics) != 1 || document.Diagnostics[0].String() != want[0].String() {
+		t.Errorf("Expected the diagnostics back, got %+v", document.Diagnostics)
+	}
+}
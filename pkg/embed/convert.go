@@ -0,0 +1,280 @@
+package embed
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Go to PHP
+// ============================================================================
+
+// ToValue converts a Go value to a PHP value:
+//
+//   - nil and nil pointers become null
+//   - bools, integers, floats and strings become their PHP scalars; byte
+//     slices become strings, unsigned integers above PHP_INT_MAX floats
+//   - slices and arrays become lists
+//   - maps become arrays, with their keys in sorted order
+//   - structs become arrays of their exported fields
+//   - *types.Value passes through unchanged
+func ToValue(v interface{}) (*types.Value, error) {
+	if value, ok := v.(*types.Value); ok {
+		if value == nil {
+			return types.NewNull(), nil
+		}
+		return value, nil
+	}
+	if v == nil {
+		return types.NewNull(), nil
+	}
+	return toValue(reflect.ValueOf(v))
+}
+
+// toValue converts a reflected Go value to a PHP value
+func toValue(rv reflect.Value) (*types.Value, error) {
+	switch rv.Kind() {
+	case reflect.Bool:
+		return types.NewBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return types.NewInt(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n := rv.Uint(); n > math.MaxInt64 {
+			return types.NewFloat(float64(n)), nil
+		}
+		return types.NewInt(int64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return types.NewFloat(rv.Float()), nil
+	case reflect.String:
+		return types.NewString(rv.String()), nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return types.NewNull(), nil
+		}
+		if value, ok := rv.Interface().(*types.Value); ok {
+			return value, nil
+		}
+		return toValue(rv.Elem())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return types.NewString(string(rv.Bytes())), nil
+		}
+		list := types.NewEmptyArray()
+		for i := 0; i < rv.Len(); i++ {
+			element, err := toValue(rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			list.Append(element)
+		}
+		return types.NewArray(list), nil
+	case reflect.Map:
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessKey(keys[i], keys[j])
+		})
+		arr := types.NewEmptyArray()
+		for _, key := range keys {
+			phpKey, err := toValue(key)
+			if err != nil {
+				return nil, err
+			}
+			if !phpKey.IsInt() && !phpKey.IsString() {
+				return nil, fmt.Errorf("embed: cannot use %s as an array key", key.Type())
+			}
+			element, err := toValue(rv.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			arr.Set(phpKey, element)
+		}
+		return types.NewArray(arr), nil
+	case reflect.Struct:
+		arr := types.NewEmptyArray()
+		for i := 0; i < rv.NumField(); i++ {
+			field := rv.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			element, err := toValue(rv.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			arr.Set(types.NewString(field.Name), element)
+		}
+		return types.NewArray(arr), nil
+	}
+	return nil, fmt.Errorf("embed: cannot convert %s to a PHP value", rv.Type())
+}
+
+// lessKey orders the keys of a map: numbers by value, others by their
+// text
+func lessKey(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.String:
+		return a.String() < b.String()
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+// ============================================================================
+// PHP to Go
+// ============================================================================
+
+// FromValue converts a PHP value to a Go value: null is nil, scalars are
+// bool, int64, float64 or string, lists are []interface{}, other arrays
+// and objects (their public properties) are map[string]interface{}.
+// Resources and closures are returned as *types.Value.
+func FromValue(value *types.Value) interface{} {
+	if value == nil {
+		return nil
+	}
+	value = value.Deref()
+	switch {
+	case value.IsNull():
+		return nil
+	case value.IsBool():
+		return value.ToBool()
+	case value.IsInt():
+		return value.ToInt()
+	case value.IsFloat():
+		return value.ToFloat()
+	case value.IsString():
+		return value.ToString()
+	case value.IsArray():
+		return fromArray(value.ToArray())
+	case value.IsObject():
+		obj := value.ToObject()
+		if obj.ClassEntry != nil && obj.ClassEntry.Name == "Closure" {
+			return value
+		}
+		properties := make(map[string]interface{})
+		for _, prop := range obj.DebugProperties() {
+			if prop.Visibility == types.VisibilityPublic {
+				properties[prop.Key.ToString()] = FromValue(prop.Value)
+			}
+		}
+		return properties
+	}
+	return value
+}
+
+// fromArray converts a list to a slice and any other array to a map
+func fromArray(arr *types.Array) interface{} {
+	list := make([]interface{}, 0, arr.Len())
+	isList := true
+	arr.Each(func(key, value *types.Value) bool {
+		if !key.IsInt() || key.ToInt() != int64(len(list)) {
+			isList = false
+			return false
+		}
+		list = append(list, FromValue(value))
+		return true
+	})
+	if isList {
+		return list
+	}
+
+	m := make(map[string]interface{}, arr.Len())
+	arr.Each(func(key, value *types.Value) bool {
+		m[key.ToString()] = FromValue(value)
+		return true
+	})
+	return m
+}
+
+// ============================================================================
+// Arguments
+// ============================================================================
+
+// valueType is the type of *types.Value, which Go functions can take to
+// receive PHP values unconverted
+var valueType = reflect.TypeOf((*types.Value)(nil))
+
+// convertArg converts a PHP argument to the type of a Go parameter,
+// describing the expected type in the error
+func convertArg(value *types.Value, to reflect.Type) (reflect.Value, error) {
+	if to == valueType {
+		return reflect.ValueOf(value), nil
+	}
+	value = value.Deref()
+
+	switch to.Kind() {
+	case reflect.Bool:
+		return reflect.ValueOf(value.ToBool()).Convert(to), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !isNumber(value) {
+			return reflect.Value{}, fmt.Errorf("must be of type int, %s given", value.TypeName())
+		}
+		return reflect.ValueOf(value.ToInt()).Convert(to), nil
+	case reflect.Float32, reflect.Float64:
+		if !isNumber(value) {
+			return reflect.Value{}, fmt.Errorf("must be of type float, %s given", value.TypeName())
+		}
+		return reflect.ValueOf(value.ToFloat()).Convert(to), nil
+	case reflect.String:
+		if value.IsArray() || value.IsObject() {
+			return reflect.Value{}, fmt.Errorf("must be of type string, %s given", value.TypeName())
+		}
+		return reflect.ValueOf(value.ToString()).Convert(to), nil
+	case reflect.Interface:
+		converted := FromValue(value)
+		if converted == nil {
+			return reflect.Zero(to), nil
+		}
+		if rv := reflect.ValueOf(converted); rv.Type().AssignableTo(to) {
+			return rv, nil
+		}
+	case reflect.Slice:
+		if to.Elem().Kind() == reflect.Uint8 && !value.IsArray() {
+			return reflect.ValueOf([]byte(value.ToString())).Convert(to), nil
+		}
+		if !value.IsArray() {
+			return reflect.Value{}, fmt.Errorf("must be of type array, %s given", value.TypeName())
+		}
+		slice := reflect.MakeSlice(to, 0, value.ToArray().Len())
+		var err error
+		value.ToArray().Each(func(_, element *types.Value) bool {
+			var converted reflect.Value
+			if converted, err = convertArg(element, to.Elem()); err == nil {
+				slice = reflect.Append(slice, converted)
+			}
+			return err == nil
+		})
+		return slice, err
+	case reflect.Map:
+		if to.Key().Kind() != reflect.String || !value.IsArray() {
+			break
+		}
+		m := reflect.MakeMapWithSize(to, value.ToArray().Len())
+		var err error
+		value.ToArray().Each(func(key, element *types.Value) bool {
+			var converted reflect.Value
+			if converted, err = convertArg(element, to.Elem()); err == nil {
+				m.SetMapIndex(reflect.ValueOf(key.ToString()).Convert(to.Key()), converted)
+			}
+			return err == nil
+		})
+		return m, err
+	}
+	return reflect.Value{}, fmt.Errorf("cannot be converted to %s", to)
+}
+
+// isNumber reports whether a value converts to a number as an int or
+// float parameter accepts it: a scalar other than a non-numeric string
+func isNumber(value *types.Value) bool {
+	if value.IsString() {
+		_, kind := types.ParseNumeric(value.ToString())
+		return kind == types.Numeric
+	}
+	return value.IsInt() || value.IsFloat() || value.IsBool() || value.IsNull()
+}
@@ -0,0 +1,130 @@
+package embed
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestToValue(t *testing.T) {
+	type point struct {
+		X, Y   int
+		hidden bool
+	}
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{nil, "NULL"},
+		{true, "bool(true)"},
+		{uint8(7), "int(7)"},
+		{1.5, "float(1.5)"},
+		{[]byte("raw"), `string(3) "raw"`},
+		{[]string{"a", "b"}, `array(2) {[0]=>string(1) "a" [1]=>string(1) "b"}`},
+		{map[string]int{"b": 2, "a": 1}, `array(2) {["a"]=>int(1) ["b"]=>int(2)}`},
+		{point{X: 1, Y: 2}, `array(2) {["X"]=>int(1) ["Y"]=>int(2)}`},
+		{(*int)(nil), "NULL"},
+	}
+	for _, tt := range tests {
+		value, err := ToValue(tt.in)
+		if err != nil {
+			t.Errorf("ToValue(%#v) error: %v", tt.in, err)
+			continue
+		}
+		if got := describe(value); got != tt.want {
+			t.Errorf("ToValue(%#v) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := ToValue(make(chan int)); err == nil {
+		t.Error("ToValue(chan) did not fail")
+	}
+	if _, err := ToValue(map[bool]int{true: 1}); err == nil {
+		t.Error("ToValue(map[bool]int) did not fail")
+	}
+}
+
+func TestFromValue(t *testing.T) {
+	list := types.NewEmptyArray()
+	list.Append(types.NewInt(1))
+	list.Append(types.NewString("two"))
+
+	hash := types.NewEmptyArray()
+	hash.Set(types.NewString("name"), types.NewString("php"))
+	hash.Set(types.NewInt(5), types.NewArray(list))
+
+	tests := []struct {
+		in   *types.Value
+		want interface{}
+	}{
+		{types.NewNull(), nil},
+		{types.NewBool(true), true},
+		{types.NewInt(42), int64(42)},
+		{types.NewFloat(0.5), 0.5},
+		{types.NewReference(types.NewString("x")), "x"},
+		{types.NewArray(list), []interface{}{int64(1), "two"}},
+		{types.NewArray(hash), map[string]interface{}{
+			"name": "php",
+			"5":    []interface{}{int64(1), "two"},
+		}},
+	}
+	for _, tt := range tests {
+		if got := FromValue(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FromValue(%v) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestConvertArg(t *testing.T) {
+	numbers := types.NewEmptyArray()
+	numbers.Append(types.NewInt(1))
+	numbers.Append(types.NewString("2"))
+
+	got, err := convertArg(types.NewArray(numbers), reflect.TypeOf([]int{}))
+	if err != nil || !reflect.DeepEqual(got.Interface(), []int{1, 2}) {
+		t.Errorf("convertArg([1, \"2\"], []int) = %v, %v", got, err)
+	}
+	if _, err := convertArg(types.NewString("abc"), reflect.TypeOf(0)); err == nil || err.Error() != "must be of type int, string given" {
+		t.Errorf("convertArg(\"abc\", int) error = %v", err)
+	}
+	if got, err := convertArg(types.NewInt(3), reflect.TypeOf("")); err != nil || got.String() != "3" {
+		t.Errorf("convertArg(3, string) = %v, %v", got, err)
+	}
+}
+
+// describe renders a value compactly in the style of var_dump()
+func describe(value *types.Value) string {
+	switch {
+	case value.IsNull():
+		return "NULL"
+	case value.IsArray():
+		s := "array(" + strconv.Itoa(value.ToArray().Len()) + ") {"
+		first := true
+		value.ToArray().Each(func(key, element *types.Value) bool {
+			if !first {
+				s += " "
+			}
+			first = false
+			if key.IsInt() {
+				s += "[" + key.ToString() + "]=>"
+			} else {
+				s += `["` + key.ToString() + `"]=>`
+			}
+			s += describe(element)
+			return true
+		})
+		return s + "}"
+	case value.IsString():
+		return "string(" + strconv.Itoa(len(value.ToString())) + `) "` + value.ToString() + `"`
+	case value.IsBool():
+		if value.ToBool() {
+			return "bool(true)"
+		}
+		return "bool(false)"
+	case value.IsInt():
+		return "int(" + value.ToString() + ")"
+	}
+	return "float(" + value.ToString() + ")"
+}
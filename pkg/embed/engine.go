@@ -0,0 +1,212 @@
+// Package embed runs PHP code from Go programs:
+//
+//	engine := embed.New()
+//	engine.RegisterFunction("greet", func(name string) string {
+//		return "Hello, " + name
+//	})
+//	result, err := engine.Execute(ctx, `echo greet($who);`, map[string]interface{}{"who": "Go"})
+//
+// Each execution runs in a VM of its own, so an engine can run scripts on
+// several goroutines at once. Values cross between Go and PHP with
+// ToValue and FromValue.
+package embed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/krizos/php-go/pkg/compiler"
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// defaultFilename names the code passed to Execute in errors
+const defaultFilename = "embedded code"
+
+// Engine runs PHP code with the Go functions and ini settings registered
+// on it
+type Engine struct {
+	mu        sync.RWMutex
+	functions map[string]vm.BuiltinFunction
+	settings  [][2]string // ini settings, in the order they were set
+	level     compiler.OptimizationLevel
+}
+
+// Result is the outcome of an execution
+type Result struct {
+	Value      interface{} // Value the code returned, converted by FromValue
+	Output     string      // Output of the code, unless sent to a writer with WithOutput
+	ExitStatus int         // Status passed to exit(), 255 after a fatal error
+}
+
+// New creates an engine without Go functions or ini settings
+func New() *Engine {
+	return &Engine{functions: make(map[string]vm.BuiltinFunction)}
+}
+
+// SetOptimizationLevel sets the optimization level of the compiler
+func (e *Engine) SetOptimizationLevel(level compiler.OptimizationLevel) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.level = level
+}
+
+// SetIni sets an ini directive for the next executions, as the
+// configuration file does
+func (e *Engine) SetIni(name, value string) error {
+	if _, err := vm.New().Config().Set(name, value, runtime.INI_SYSTEM); err != nil {
+		return fmt.Errorf("embed: %w", err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.settings = append(e.settings, [2]string{name, value})
+	return nil
+}
+
+// RegisterFunction makes a Go function callable from PHP under a name.
+// Its parameters may be of the basic Go types, slices and string-keyed
+// maps of them, interface{} or *types.Value; it may return a value, an
+// error, or both. An error is thrown in PHP as an Exception. A
+// vm.BuiltinFunction is registered as it is.
+func (e *Engine) RegisterFunction(name string, fn interface{}) error {
+	builtin, err := wrapFunction(name, fn)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.functions[name] = builtin
+	return nil
+}
+
+// ============================================================================
+// Execution
+// ============================================================================
+
+// Option changes how a single execution runs
+type Option func(*execution)
+
+// execution is the setup of a single execution
+type execution struct {
+	output   io.Writer
+	filename string
+}
+
+// WithOutput sends the output of the code to w as it is produced, instead
+// of collecting it in the result
+func WithOutput(w io.Writer) Option {
+	return func(x *execution) {
+		x.output = w
+	}
+}
+
+// WithFilename sets the file name errors and __FILE__ report for code
+// passed to Execute
+func WithFilename(name string) Option {
+	return func(x *execution) {
+		x.filename = name
+	}
+}
+
+// Execute runs PHP code with variables in its global scope, converted by
+// ToValue. The code may start with an opening <?php tag; without one it is
+// taken as PHP code. Canceling the context, or reaching its deadline,
+// stops the code.
+//
+// A failing script returns its error along with the result, which holds
+// the output produced before the failure.
+func (e *Engine) Execute(ctx context.Context, code string, vars map[string]interface{}, options ...Option) (*Result, error) {
+	x := execution{filename: defaultFilename}
+	for _, option := range options {
+		option(&x)
+	}
+	if !strings.HasPrefix(strings.TrimLeft(code, " \t\r\n"), "<?") {
+		code = "<?php " + code
+	}
+
+	script, err := e.compiler()(x.filename, []byte(code))
+	if err != nil {
+		return nil, err
+	}
+	return e.run(ctx, script, vars, x)
+}
+
+// ExecuteFile runs a PHP file like Execute
+func (e *Engine) ExecuteFile(ctx context.Context, path string, vars map[string]interface{}, options ...Option) (*Result, error) {
+	x := execution{}
+	for _, option := range options {
+		option(&x)
+	}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	script, err := e.compiler()(path, source)
+	if err != nil {
+		return nil, err
+	}
+	return e.run(ctx, script, vars, x)
+}
+
+// compiler returns the compiler of the engine's optimization level
+func (e *Engine) compiler() vm.ScriptCompiler {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return compiler.ScriptCompiler(e.level)
+}
+
+// newVM creates the VM of an execution, with the functions, settings and
+// variables set
+func (e *Engine) newVM(ctx context.Context, vars map[string]interface{}) (*vm.VM, error) {
+	machine := vm.New()
+	machine.SetContext(ctx)
+
+	e.mu.RLock()
+	machine.SetScriptCompiler(compiler.ScriptCompiler(e.level))
+	for _, setting := range e.settings {
+		machine.Config().Set(setting[0], setting[1], runtime.INI_SYSTEM)
+	}
+	for name, fn := range e.functions {
+		machine.RegisterBuiltin(name, fn)
+	}
+	e.mu.RUnlock()
+
+	for name, v := range vars {
+		value, err := ToValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("embed: variable $%s: %w", name, err)
+		}
+		machine.SetGlobal(name, value)
+	}
+	return machine, nil
+}
+
+// run executes a compiled script
+func (e *Engine) run(ctx context.Context, script *vm.Script, vars map[string]interface{}, x execution) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	machine, err := e.newVM(ctx, vars)
+	if err != nil {
+		return nil, err
+	}
+	if x.output != nil {
+		machine.SetOutputWriter(x.output)
+	}
+
+	err = machine.ExecuteScript(script)
+	return &Result{
+		Value:      FromValue(machine.Result()),
+		Output:     machine.GetOutput(),
+		ExitStatus: machine.ExitStatus(),
+	}, err
+}
@@ -0,0 +1,203 @@
+package embed
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+func TestEngine_Execute(t *testing.T) {
+	engine := New()
+	result, err := engine.Execute(context.Background(), `echo $name ?? "nobody";`, map[string]interface{}{"name": "Go"})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if result.Output != "Go" || result.ExitStatus != 0 {
+		t.Errorf("result = %+v", result)
+	}
+
+	// An opening tag is accepted too
+	result, err = engine.Execute(context.Background(), "<?php echo 1 + 2;", nil)
+	if err != nil || result.Output != "3" {
+		t.Errorf("Execute(<?php) = %+v, %v", result, err)
+	}
+}
+
+func TestEngine_WithOutput(t *testing.T) {
+	var output strings.Builder
+	result, err := New().Execute(context.Background(), `echo $greeting ?? "";`,
+		map[string]interface{}{"greeting": "hello"}, WithOutput(&output))
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if output.String() != "hello" || result.Output != "" {
+		t.Errorf("writer = %q, result output = %q", output.String(), result.Output)
+	}
+}
+
+func TestEngine_ExecuteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.php")
+	if err := os.WriteFile(path, []byte(`<?php echo $_GET["id"] ?? "none";`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := New().ExecuteFile(context.Background(), path, nil)
+	if err != nil || result.Output != "none" {
+		t.Errorf("ExecuteFile = %+v, %v", result, err)
+	}
+	if _, err := New().ExecuteFile(context.Background(), path+".missing", nil); err == nil {
+		t.Error("ExecuteFile of a missing file did not fail")
+	}
+}
+
+func TestEngine_RegisterFunction(t *testing.T) {
+	engine := New()
+	if err := engine.RegisterFunction("greet", func(name string, times int) string {
+		return strings.Repeat("Hello, "+name+"! ", times)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RegisterFunction("sum", func(numbers ...float64) float64 {
+		total := 0.0
+		for _, n := range numbers {
+			total += n
+		}
+		return total
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RegisterFunction("fail", func() error {
+		return errors.New("it broke")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RegisterFunction("pair", func() (int, int) { return 1, 2 }); err == nil {
+		t.Error("a function with two results was registered")
+	}
+	if err := engine.RegisterFunction("number", 42); err == nil {
+		t.Error("a non-function was registered")
+	}
+
+	machine, err := engine.newVM(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(name string, args ...*types.Value) (*types.Value, error) {
+		return machine.CallCallable(types.NewString(name), args)
+	}
+
+	result, err := call("greet", types.NewString("PHP"), types.NewString("2"))
+	if err != nil || result.ToString() != "Hello, PHP! Hello, PHP! " {
+		t.Errorf("greet() = %v, %v", result, err)
+	}
+	result, err = call("sum", types.NewInt(1), types.NewFloat(2.5))
+	if err != nil || result.ToFloat() != 3.5 {
+		t.Errorf("sum() = %v, %v", result, err)
+	}
+
+	errorTests := []struct {
+		name string
+		args []*types.Value
+		want string
+	}{
+		{"greet", []*types.Value{types.NewString("PHP")}, "greet() expects exactly 2 arguments, 1 given"},
+		{"greet", []*types.Value{types.NewString("PHP"), types.NewString("x")}, "greet(): Argument #2 must be of type int, string given"},
+		{"fail", nil, "it broke"},
+	}
+	for _, tt := range errorTests {
+		_, err := call(tt.name, tt.args...)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestEngine_ContextTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// while (true) {}
+	script := &vm.Script{Path: "loop.php", Instructions: vm.Instructions{
+		{Opcode: vm.OpJmp, Op1: vm.Operand{Value: 0}},
+	}}
+	done := make(chan error, 1)
+	go func() {
+		_, err := New().run(ctx, script, nil, execution{})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the script was not stopped")
+	}
+
+	if _, err := New().Execute(ctx, "echo 1;", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Execute with an expired context: error = %v", err)
+	}
+}
+
+func TestEngine_SetIni(t *testing.T) {
+	engine := New()
+	if err := engine.SetIni("precision", "5"); err != nil {
+		t.Fatal(err)
+	}
+	machine, err := engine.newVM(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := machine.Config().Get("precision"); got != "5" {
+		t.Errorf("precision = %q", got)
+	}
+}
+
+func TestEngine_ExecuteRegisteredFunctions(t *testing.T) {
+	// The flow of the package documentation
+	engine := New()
+	if err := engine.RegisterFunction("greet", func(name string) string {
+		return "Hello, " + name
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RegisterFunction("upper", strings.ToUpper); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RegisterFunction("fail", func() error {
+		return errors.New("it broke")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		code   string
+		vars   map[string]interface{}
+		output string
+		value  interface{}
+	}{
+		{`echo greet($who);`, map[string]interface{}{"who": "Go"}, "Hello, Go", nil},
+		{`echo upper("php");`, nil, "PHP", nil},
+		{`$x = 2; echo $x;`, nil, "2", nil},
+		{`$s = upper(greet($who)); echo $s, " ", greet("b");`, map[string]interface{}{"who": "a"}, "HELLO, A Hello, b", nil},
+		{`try { fail(); } catch (Exception $e) { echo $e->getMessage(); }`, nil, "it broke", nil},
+		{`return $n * 2;`, map[string]interface{}{"n": 21}, "", int64(42)},
+	}
+	for _, tt := range tests {
+		result, err := engine.Execute(context.Background(), tt.code, tt.vars)
+		if err != nil {
+			t.Errorf("Execute(%q) error: %v", tt.code, err)
+			continue
+		}
+		if result.Output != tt.output || result.Value != tt.value {
+			t.Errorf("Execute(%q) = %q, %v; want %q, %v", tt.code, result.Output, result.Value, tt.output, tt.value)
+		}
+	}
+}
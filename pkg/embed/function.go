@@ -0,0 +1,94 @@
+package embed
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ============================================================================
+// Go Functions
+// ============================================================================
+
+// errorType is the type of the error a Go function may return last
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// wrapFunction turns a Go function into a PHP builtin. Its arguments are
+// converted to the types of the parameters (see convertArg) and its
+// result with ToValue; a trailing error result is thrown as an Exception.
+// A vm.BuiltinFunction is used as it is.
+func wrapFunction(name string, fn interface{}) (vm.BuiltinFunction, error) {
+	switch builtin := fn.(type) {
+	case vm.BuiltinFunction:
+		return builtin, nil
+	case func(*vm.VM, []*types.Value) (*types.Value, error):
+		return builtin, nil
+	}
+
+	rv := reflect.ValueOf(fn)
+	if rv.Kind() != reflect.Func || rv.IsNil() {
+		return nil, fmt.Errorf("embed: %s must be a function, %T given", name, fn)
+	}
+	t := rv.Type()
+	returnsError := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+	results := t.NumOut()
+	if returnsError {
+		results--
+	}
+	if results > 1 {
+		return nil, fmt.Errorf("embed: %s returns %d values; a function returns at most a value and an error", name, results)
+	}
+
+	required := t.NumIn()
+	if t.IsVariadic() {
+		required--
+	}
+	return func(machine *vm.VM, args []*types.Value) (*types.Value, error) {
+		if len(args) < required || !t.IsVariadic() && len(args) > required {
+			expects := "exactly"
+			if t.IsVariadic() {
+				expects = "at least"
+			}
+			return nil, machine.ThrowError("ArgumentCountError", "%s() expects %s %d argument%s, %d given",
+				name, expects, required, plural(required), len(args))
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			paramType := t.In(min(i, t.NumIn()-1))
+			if t.IsVariadic() && i >= required {
+				paramType = t.In(t.NumIn() - 1).Elem()
+			}
+			converted, err := convertArg(arg, paramType)
+			if err != nil {
+				return nil, machine.ThrowError("TypeError", "%s(): Argument #%d %s", name, i+1, err)
+			}
+			in[i] = converted
+		}
+
+		out := rv.Call(in)
+		if returnsError {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return nil, machine.ThrowError("Exception", "%s", err.Error())
+			}
+		}
+		if results == 0 {
+			return types.NewNull(), nil
+		}
+		result, err := ToValue(out[0].Interface())
+		if err != nil {
+			return nil, machine.ThrowError("Error", "%s(): %s", name, err)
+		}
+		return result, nil
+	}, nil
+}
+
+// plural returns the "s" of a count other than one
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
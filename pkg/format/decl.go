@@ -0,0 +1,279 @@
+package format
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+// ============================================================================
+// Declarations
+// ============================================================================
+
+// declaration lays out a declaration or class member
+func (p *printer) declaration(node ast.Node) doc {
+	switch s := node.(type) {
+	case *ast.FunctionDeclaration:
+		head := cat(text("function "+reference(s.ByRef)), p.expr(s.Name))
+		return cat(p.attributes(s.Attributes), p.function(head, s.Parameters, s.ReturnType, s.Body, s.End()))
+	case *ast.MethodDeclaration:
+		var modifiers []string
+		if s.Abstract {
+			modifiers = append(modifiers, "abstract")
+		}
+		if s.Final {
+			modifiers = append(modifiers, "final")
+		}
+		if s.Visibility != "" {
+			modifiers = append(modifiers, s.Visibility)
+		}
+		if s.Static {
+			modifiers = append(modifiers, "static")
+		}
+		modifiers = append(modifiers, "function "+reference(s.ByRef))
+		head := cat(text(strings.Join(modifiers, " ")), p.expr(s.Name))
+		return cat(p.attributes(s.Attributes), p.function(head, s.Parameters, s.ReturnType, s.Body, s.End()))
+	case *ast.ClassDeclaration:
+		header := concat{text(strings.Join(append(append([]string{}, s.Modifiers...), "class "), " ")), p.expr(s.Name)}
+		if s.Extends != nil {
+			header = append(header, text(" extends "), p.expr(s.Extends))
+		}
+		header = append(header, p.names(" implements ", s.Implements))
+		return cat(p.attributes(s.Attributes), p.classBody(header, s.Body, s.End()))
+	case *ast.InterfaceDeclaration:
+		header := cat(text("interface "), p.expr(s.Name), p.names(" extends ", s.Extends))
+		members := make([]entry, 0, len(s.Constants)+len(s.Body))
+		for _, c := range s.Constants {
+			c := c
+			members = append(members, entry{pos: c.Pos(), end: c.End(), kind: statementKind(c), print: func() doc { return p.statement(c) }})
+		}
+		for _, m := range s.Body {
+			m := m
+			members = append(members, entry{pos: m.Token.Pos, end: signatureEnd(m), kind: spacedEntry, print: func() doc {
+				head := cat(text("public function "+reference(m.ByRef)), p.expr(m.Name))
+				return cat(p.attributes(m.Attributes), p.function(head, m.Parameters, m.ReturnType, nil, signatureEnd(m)))
+			}})
+		}
+		// Constants and methods may interleave
+		sort.SliceStable(members, func(i, j int) bool { return members[j].pos.After(members[i].pos) })
+		return cat(p.attributes(s.Attributes), header, p.opening(true), p.braces(p.sequence(members, s.End())))
+	case *ast.TraitDeclaration:
+		p.inTrait = true
+		defer func() { p.inTrait = false }()
+		return cat(p.attributes(s.Attributes), p.classBody(cat(text("trait "), p.expr(s.Name)), s.Body, s.End()))
+	case *ast.EnumDeclaration:
+		header := concat{text("enum "), p.expr(s.Name)}
+		if s.BackingType != nil {
+			header = append(header, text(": "), p.expr(s.BackingType))
+		}
+		header = append(header, p.names(" implements ", s.Implements))
+		return cat(p.attributes(s.Attributes), p.classBody(header, s.Body, s.End()))
+	case *ast.EnumCaseDeclaration:
+		out := concat{p.attributes(s.Attributes), text("case "), p.expr(s.Name)}
+		if s.Value != nil {
+			out = append(out, text(" = "), p.expr(s.Value))
+		}
+		return append(out, text(";"))
+	case *ast.PropertyDeclaration:
+		return p.property(s)
+	case *ast.ClassConstantDeclaration:
+		out := concat{p.attributes(s.Attributes)}
+		if s.Visibility != "" && !p.inTrait {
+			out = append(out, text(s.Visibility+" "))
+		}
+		return append(out, text("const "), p.constants(s.Constants), text(";"))
+	case *ast.TraitUse:
+		return p.traitUse(s)
+	case *ast.TraitPrecedence:
+		return cat(p.traitMethod(s.TraitName, s.MethodName), text(" insteadof "), p.names("", s.Instead), text(";"))
+	case *ast.TraitAlias:
+		out := concat{p.traitMethod(s.TraitName, s.MethodName), text(" as")}
+		if s.Visibility != "" {
+			out = append(out, text(" "+s.Visibility))
+		}
+		if s.Alias != nil {
+			out = append(out, text(" "), p.expr(s.Alias))
+		}
+		return append(out, text(";"))
+	}
+	// A statement the printer does not know is kept as written
+	return text(p.source(node))
+}
+
+// reference returns the & of a function returning by reference
+func reference(byRef bool) string {
+	if byRef {
+		return "&"
+	}
+	return ""
+}
+
+// names lays out a keyword followed by a list of names, if any
+func (p *printer) names(keyword string, names []*ast.Identifier) doc {
+	if len(names) == 0 {
+		return nil
+	}
+	docs := make([]doc, len(names))
+	for i, name := range names {
+		docs[i] = p.expr(name)
+	}
+	return cat(text(keyword), join(text(", "), docs))
+}
+
+// attributes lays out the attribute groups of a declaration, one per line
+func (p *printer) attributes(groups []*ast.AttributeGroup) doc {
+	out := concat{}
+	for _, g := range groups {
+		out = append(out, p.attributeGroup(g), hardline)
+	}
+	return out
+}
+
+// inlineAttributes lays out the attribute groups of a parameter or
+// closure, on its line
+func (p *printer) inlineAttributes(groups []*ast.AttributeGroup) doc {
+	out := concat{}
+	for _, g := range groups {
+		out = append(out, p.attributeGroup(g), text(" "))
+	}
+	return out
+}
+
+// classBody lays out a class-like declaration from its header
+func (p *printer) classBody(header doc, body []ast.Stmt, end lexer.Position) doc {
+	return cat(header, p.opening(true), p.braces(p.statements(body, end)))
+}
+
+// signatureEnd returns where the signature of an interface method ends
+func signatureEnd(m *ast.MethodSignature) lexer.Position {
+	end := m.Name.End()
+	for _, param := range m.Parameters {
+		if param.Name.End().After(end) {
+			end = param.Name.End()
+		}
+		if param.DefaultValue != nil && param.DefaultValue.End().After(end) {
+			end = param.DefaultValue.End()
+		}
+	}
+	if m.ReturnType != nil && m.ReturnType.End().After(end) {
+		end = m.ReturnType.End()
+	}
+	return end
+}
+
+// function lays out a function, method or abstract method from what
+// precedes its parameters. When the parameters break, the opening brace
+// follows the closing parenthesis.
+func (p *printer) function(head doc, params []*ast.Parameter, returnType ast.Expr, body *ast.BlockStatement, end lexer.Position) doc {
+	paramsEnd := end
+	if returnType != nil {
+		paramsEnd = returnType.Pos()
+	} else if body != nil {
+		paramsEnd = body.Pos()
+	}
+
+	suffix := concat{}
+	if returnType != nil {
+		suffix = append(suffix, text(": "), p.expr(returnType))
+	}
+	if body == nil {
+		suffix = append(suffix, text(";"))
+		return cat(head, p.parameters(params, paramsEnd, suffix))
+	}
+	suffix = append(suffix, ifBreak{broken: text(" {"), flat: cat(p.opening(true), text("{"))})
+	statements := p.statements(body.Statements, body.End())
+	if len(statements) == 0 {
+		return cat(head, p.parameters(params, paramsEnd, suffix), hardline, text("}"))
+	}
+	return cat(head, p.parameters(params, paramsEnd, suffix), indent(hardline, statements), hardline, text("}"))
+}
+
+// parameters lays out a parameter list
+func (p *printer) parameters(params []*ast.Parameter, end lexer.Position, suffix doc) doc {
+	items := make([]item, len(params))
+	for i, param := range params {
+		param := param
+		pos := param.Name.Pos()
+		if len(param.Attributes) > 0 {
+			pos = param.Attributes[0].Pos()
+		} else if param.Type != nil {
+			pos = param.Type.Pos()
+		}
+		items[i] = item{pos: pos, end: param.Name.End(), print: func() doc { return p.parameter(param) }}
+		if param.DefaultValue != nil {
+			items[i].end = param.DefaultValue.End()
+		}
+	}
+	return p.list("(", ")", items, end, listStyle{}, suffix)
+}
+
+func (p *printer) parameter(param *ast.Parameter) doc {
+	out := concat{p.inlineAttributes(param.Attributes)}
+	if param.Type != nil {
+		out = append(out, p.expr(param.Type), text(" "))
+	}
+	if param.ByRef {
+		out = append(out, text("&"))
+	}
+	if param.Variadic {
+		out = append(out, text("..."))
+	}
+	out = append(out, p.expr(param.Name))
+	if param.DefaultValue != nil {
+		out = append(out, text(" = "), p.expr(param.DefaultValue))
+	}
+	return out
+}
+
+// property lays out a property declaration. PSR-12 requires a visibility:
+// a property declared without one is public, and is declared with var.
+func (p *printer) property(s *ast.PropertyDeclaration) doc {
+	var modifiers []string
+	if s.Visibility != "" {
+		modifiers = append(modifiers, s.Visibility)
+	} else if !s.Static && !s.Readonly {
+		modifiers = append(modifiers, "var")
+	}
+	if s.Static {
+		modifiers = append(modifiers, "static")
+	}
+	if s.Readonly {
+		modifiers = append(modifiers, "readonly")
+	}
+	out := concat{p.attributes(s.Attributes), text(strings.Join(modifiers, " ") + " ")}
+	if s.Type != nil {
+		out = append(out, p.expr(s.Type), text(" "))
+	}
+	items := make([]doc, len(s.Properties))
+	for i, prop := range s.Properties {
+		items[i] = p.expr(prop.Name)
+		if prop.DefaultValue != nil {
+			items[i] = cat(items[i], text(" = "), p.expr(prop.DefaultValue))
+		}
+	}
+	return append(out, join(text(", "), items), text(";"))
+}
+
+// traitUse lays out the use of traits in a class, with its adaptations
+func (p *printer) traitUse(s *ast.TraitUse) doc {
+	head := cat(text("use "), p.names("", s.Traits))
+	if len(s.Adaptations) == 0 {
+		return cat(head, text(";"))
+	}
+	adaptations := make([]entry, len(s.Adaptations))
+	for i, a := range s.Adaptations {
+		a := a
+		adaptations[i] = entry{pos: a.Pos(), end: a.End(), print: func() doc { return p.declaration(a) }}
+	}
+	return cat(head, text(" "), p.braces(p.sequence(adaptations, s.End())))
+}
+
+// traitMethod lays out the method an adaptation is about
+func (p *printer) traitMethod(trait, method *ast.Identifier) doc {
+	if trait == nil {
+		return p.expr(method)
+	}
+	return cat(p.expr(trait), text("::"), p.expr(method))
+}
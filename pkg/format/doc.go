@@ -0,0 +1,246 @@
+package format
+
+import "strings"
+
+// ============================================================================
+// Documents
+// ============================================================================
+
+// The printer lays code out as a document: text, and line breaks that a
+// group prints as spaces when its content fits on the rest of the line,
+// and as newlines otherwise (Wadler's "prettier printer").
+type doc interface{}
+
+// text is printed as is. Text containing newlines, such as a heredoc, is
+// never reindented.
+type text string
+
+// lineBreak is a newline in a broken group and a space, or nothing when
+// soft, in a flat one. A hard break is always a newline and breaks the
+// groups containing it.
+type lineBreak struct {
+	soft, hard bool
+}
+
+var (
+	line      = lineBreak{}
+	softline  = lineBreak{soft: true}
+	hardline  = lineBreak{hard: true}
+	blankline = concat{hardline, hardline}
+)
+
+// breakParent breaks the groups containing it without printing anything
+type breakParent struct{}
+
+// concat is a sequence of documents
+type concat []doc
+
+// nest indents the lines its document breaks by one level
+type nest struct {
+	doc doc
+}
+
+// group prints its document flat if it fits on the line, broken otherwise
+type group struct {
+	doc  doc
+	hard bool // Contains a hard break: always broken
+}
+
+// ifBreak prints broken in a broken group and flat in a flat one
+type ifBreak struct {
+	broken, flat doc
+}
+
+// cat concatenates documents
+func cat(docs ...doc) doc {
+	return concat(docs)
+}
+
+// indent nests documents
+func indent(docs ...doc) doc {
+	return nest{concat(docs)}
+}
+
+// grouped groups documents
+func grouped(docs ...doc) doc {
+	d := concat(docs)
+	return &group{doc: d, hard: hasHard(d)}
+}
+
+// join separates documents by a separator
+func join(sep doc, docs []doc) doc {
+	out := make(concat, 0, 2*len(docs))
+	for i, d := range docs {
+		if i > 0 {
+			out = append(out, sep)
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// hasHard reports whether a document contains a hard break outside of the
+// groups it contains, which record their own
+func hasHard(d doc) bool {
+	switch d := d.(type) {
+	case lineBreak:
+		return d.hard
+	case breakParent:
+		return true
+	case concat:
+		for _, part := range d {
+			if hasHard(part) {
+				return true
+			}
+		}
+	case nest:
+		return hasHard(d.doc)
+	case *group:
+		return d.hard
+	case ifBreak:
+		return hasHard(d.broken)
+	}
+	return false
+}
+
+// ============================================================================
+// Rendering
+// ============================================================================
+
+type mode int
+
+const (
+	modeBreak mode = iota
+	modeFlat
+)
+
+// command is a document to print at an indentation level, in a mode
+type command struct {
+	level int
+	mode  mode
+	doc   doc
+}
+
+// renderer prints a document within a line length
+type renderer struct {
+	out    []byte
+	indent string
+	width  int // Line length
+	column int
+}
+
+// render prints a document
+func render(d doc, indent string, width int) string {
+	r := &renderer{indent: indent, width: width}
+	stack := []command{{doc: d}}
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch d := c.doc.(type) {
+		case text:
+			r.write(string(d))
+		case concat:
+			for i := len(d) - 1; i >= 0; i-- {
+				stack = append(stack, command{c.level, c.mode, d[i]})
+			}
+		case nest:
+			stack = append(stack, command{c.level + 1, c.mode, d.doc})
+		case *group:
+			m := modeBreak
+			if !d.hard && (c.mode == modeFlat || r.fits(command{c.level, modeFlat, d.doc}, stack)) {
+				m = modeFlat
+			}
+			stack = append(stack, command{c.level, m, d.doc})
+		case lineBreak:
+			if c.mode == modeFlat && !d.hard {
+				if !d.soft {
+					r.write(" ")
+				}
+				continue
+			}
+			r.newline(c.level)
+		case ifBreak:
+			if c.mode == modeBreak {
+				stack = append(stack, command{c.level, c.mode, d.broken})
+			} else {
+				stack = append(stack, command{c.level, c.mode, d.flat})
+			}
+		}
+	}
+	return string(r.out)
+}
+
+// write prints text, tracking the column
+func (r *renderer) write(s string) {
+	r.out = append(r.out, s...)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		r.column = len(s) - i - 1
+	} else {
+		r.column += len(s)
+	}
+}
+
+// newline ends the line, without trailing whitespace, and indents the next
+func (r *renderer) newline(level int) {
+	for n := len(r.out); n > 0 && (r.out[n-1] == ' ' || r.out[n-1] == '\t'); n-- {
+		r.out = r.out[:n-1]
+	}
+	r.out = append(r.out, '\n')
+	r.column = 0
+	for i := 0; i < level; i++ {
+		r.out = append(r.out, r.indent...)
+		r.column += len(strings.ReplaceAll(r.indent, "\t", "    "))
+	}
+}
+
+// fits reports whether a command printed flat, followed by the rest of the
+// stack up to its next line break, fits on the current line
+func (r *renderer) fits(next command, rest []command) bool {
+	width := r.width - r.column
+	stack := []command{next}
+	for width >= 0 {
+		if len(stack) == 0 {
+			if len(rest) == 0 {
+				return true
+			}
+			stack = append(stack, rest[len(rest)-1])
+			rest = rest[:len(rest)-1]
+			continue
+		}
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch d := c.doc.(type) {
+		case text:
+			if i := strings.IndexByte(string(d), '\n'); i >= 0 {
+				return width-i >= 0
+			}
+			width -= len(d)
+		case concat:
+			for i := len(d) - 1; i >= 0; i-- {
+				stack = append(stack, command{c.level, c.mode, d[i]})
+			}
+		case nest:
+			stack = append(stack, command{c.level, c.mode, d.doc})
+		case *group:
+			m := c.mode
+			if d.hard {
+				m = modeBreak
+			}
+			stack = append(stack, command{c.level, m, d.doc})
+		case lineBreak:
+			if c.mode == modeBreak || d.hard {
+				return true
+			}
+			if !d.soft {
+				width--
+			}
+		case ifBreak:
+			if c.mode == modeBreak {
+				stack = append(stack, command{c.level, c.mode, d.broken})
+			} else {
+				stack = append(stack, command{c.level, c.mode, d.flat})
+			}
+		}
+	}
+	return false
+}
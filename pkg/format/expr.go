@@ -0,0 +1,347 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+)
+
+// ============================================================================
+// Expressions
+// ============================================================================
+
+// breakingOperators are the binary operators a long operation breaks
+// before
+var breakingOperators = map[string]bool{
+	".": true, "&&": true, "||": true, "and": true, "or": true, "xor": true, "??": true,
+}
+
+// expr lays out an expression
+func (p *printer) expr(e ast.Expr) doc {
+	switch e := e.(type) {
+	case nil:
+		return nil
+	case *ast.Identifier:
+		return text(e.Value)
+	case *ast.Variable:
+		return text(e.String())
+	case *ast.VariableVariable:
+		switch e.Name.(type) {
+		case *ast.Variable, *ast.VariableVariable:
+			return cat(text("$"), p.expr(e.Name))
+		}
+		return cat(text("${"), p.expr(e.Name), text("}"))
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.InterpolatedStringExpression, *ast.MagicConstant:
+		return text(p.source(e))
+	case *ast.BooleanLiteral:
+		if e.Value {
+			return text("true")
+		}
+		return text("false")
+	case *ast.NullLiteral:
+		return text("null")
+	case *ast.PrefixExpression:
+		if e.Operator == "++(postfix)" || e.Operator == "--(postfix)" {
+			return cat(p.expr(e.Right), text(strings.TrimSuffix(e.Operator, "(postfix)")))
+		}
+		if right, ok := e.Right.(*ast.PrefixExpression); ok && (e.Operator == "-" || e.Operator == "+") &&
+			strings.HasPrefix(right.Operator, e.Operator) {
+			return cat(text(e.Operator+" "), p.expr(e.Right)) // Not to print -- or ++
+		}
+		return cat(text(e.Operator), p.expr(e.Right))
+	case *ast.InfixExpression:
+		return p.infix(e, true)
+	case *ast.AssignmentExpression:
+		return cat(p.expr(e.Left), text(" "+e.Operator+" "), p.expr(e.Right))
+	case *ast.TernaryExpression:
+		if e.Consequence == nil {
+			return grouped(p.expr(e.Condition), indent(line, text("?: "), p.expr(e.Alternative)))
+		}
+		return grouped(p.expr(e.Condition), indent(line, text("? "), p.expr(e.Consequence), line, text(": "), p.expr(e.Alternative)))
+	case *ast.ArrayExpression:
+		return p.list("[", "]", p.elements(e.Elements), e.End(), listStyle{trailingComma: true, hug: true}, nil)
+	case *ast.ListExpression:
+		if e.Token.Literal != "[" {
+			return cat(text("list"), p.list("(", ")", p.elements(e.Elements), e.End(), listStyle{}, nil))
+		}
+		return p.list("[", "]", p.elements(e.Elements), e.End(), listStyle{}, nil)
+	case *ast.IndexExpression:
+		return cat(p.expr(e.Left), text("["), p.expr(e.Index), text("]"))
+	case *ast.PropertyExpression, *ast.NullsafePropertyExpression, *ast.MethodCallExpression:
+		return p.chain(e)
+	case *ast.StaticPropertyExpression:
+		return cat(p.expr(e.Class), text("::"), p.member(e.Property))
+	case *ast.CallExpression:
+		return cat(p.expr(e.Function), p.arguments(e.Arguments, e.End()))
+	case *ast.StaticCallExpression:
+		return cat(p.expr(e.Class), text("::"), p.member(e.Method), p.arguments(e.Arguments, e.End()))
+	case *ast.VariadicPlaceholder:
+		return text("...")
+	case *ast.SpreadExpression:
+		return cat(text("..."), p.expr(e.Value))
+	case *ast.NamedArgument:
+		return cat(text(e.Name+": "), p.expr(e.Value))
+	case *ast.NewExpression:
+		return cat(text("new "), p.expr(e.Class), p.arguments(e.Arguments, e.End()))
+	case *ast.InstanceofExpression:
+		return cat(p.expr(e.Left), text(" instanceof "), p.expr(e.Right))
+	case *ast.CastExpression:
+		return cat(text("("+e.Type+") "), p.expr(e.Expr))
+	case *ast.IncludeExpression:
+		return cat(text(e.Kind+" "), p.expr(e.Path))
+	case *ast.ExitExpression:
+		if e.Status == nil {
+			return text(e.Token.Literal)
+		}
+		return cat(text(e.Token.Literal+"("), p.expr(e.Status), text(")"))
+	case *ast.IssetExpression:
+		return cat(text("isset"), p.list("(", ")", p.exprItems(e.Variables), e.End(), listStyle{}, nil))
+	case *ast.EmptyExpression:
+		return cat(text("empty("), p.expr(e.Expr), text(")"))
+	case *ast.GroupedExpression:
+		return cat(text("("), p.expr(e.Expr), text(")"))
+	case *ast.ClosureExpression:
+		return p.closure(e)
+	case *ast.ArrowFunctionExpression:
+		head := concat{p.inlineAttributes(e.Attributes)}
+		if e.Static {
+			head = append(head, text("static "))
+		}
+		head = append(head, text("fn "+reference(e.ByRef)))
+		end := e.Body.Pos()
+		suffix := concat{}
+		if e.ReturnType != nil {
+			end = e.ReturnType.Pos()
+			suffix = append(suffix, text(": "), p.expr(e.ReturnType))
+		}
+		return cat(head, p.parameters(e.Parameters, end, suffix), text(" => "), p.expr(e.Body))
+	case *ast.MatchExpression:
+		return p.match(e)
+	case *ast.NullableType:
+		return cat(text("?"), p.expr(e.Type))
+	case *ast.UnionType:
+		types := make([]doc, len(e.Types))
+		for i, t := range e.Types {
+			types[i] = p.expr(t)
+			if _, ok := t.(*ast.IntersectionType); ok {
+				types[i] = cat(text("("), types[i], text(")"))
+			}
+		}
+		return join(text("|"), types)
+	case *ast.IntersectionType:
+		types := make([]doc, len(e.Types))
+		for i, t := range e.Types {
+			types[i] = p.expr(t)
+		}
+		return join(text("&"), types)
+	}
+	// An expression the printer does not know is kept as written
+	return text(p.source(e))
+}
+
+// attributeGroup lays out a #[...] group
+func (p *printer) attributeGroup(g *ast.AttributeGroup) doc {
+	attrs := make([]doc, len(g.Attributes))
+	for i, a := range g.Attributes {
+		attrs[i] = p.expr(a.Name)
+		if a.Arguments != nil {
+			attrs[i] = cat(attrs[i], p.arguments(a.Arguments, a.End()))
+		}
+	}
+	return cat(text("#["), join(text(", "), attrs), text("]"))
+}
+
+// member lays out the name of a member after -> or ::, in braces when it
+// is computed
+func (p *printer) member(e ast.Expr) doc {
+	switch e.(type) {
+	case *ast.Identifier, *ast.Variable, *ast.VariableVariable:
+		return p.expr(e)
+	}
+	return cat(text("{"), p.expr(e), text("}"))
+}
+
+// elements returns the list items of array elements. The empty elements
+// of a destructuring skip a value.
+func (p *printer) elements(elements []ast.ArrayElement) []item {
+	items := make([]item, 0, len(elements))
+	for _, el := range elements {
+		el := el
+		if el.Value == nil {
+			// An empty element is positioned at the next, or the end
+			items = append(items, item{print: func() doc { return nil }})
+			continue
+		}
+		it := item{pos: el.Value.Pos(), end: el.Value.End(), print: func() doc {
+			out := concat{}
+			if el.Key != nil {
+				out = append(out, p.expr(el.Key), text(" => "))
+			}
+			if el.ByRef {
+				out = append(out, text("&"))
+			}
+			return append(out, p.expr(el.Value))
+		}}
+		if el.Key != nil {
+			it.pos = el.Key.Pos()
+		}
+		items = append(items, it)
+	}
+	for i := len(items) - 1; i >= 0; i-- {
+		if !items[i].pos.IsValid() && i+1 < len(items) {
+			items[i].pos, items[i].end = items[i+1].pos, items[i+1].pos
+		}
+	}
+	return items
+}
+
+// infix lays out a binary operation. A chain of the same operator breaks
+// before each operator when it does not fit, the operands after the first
+// indented unless the chain is on lines of its own.
+func (p *printer) infix(e *ast.InfixExpression, indented bool) doc {
+	var operands []ast.Expr
+	var collect func(ast.Expr)
+	collect = func(operand ast.Expr) {
+		if infix, ok := operand.(*ast.InfixExpression); ok && infix.Operator == e.Operator {
+			collect(infix.Left)
+			collect(infix.Right)
+			return
+		}
+		operands = append(operands, operand)
+	}
+	collect(e)
+
+	if !breakingOperators[strings.ToLower(e.Operator)] {
+		docs := make([]doc, len(operands))
+		for i, operand := range operands {
+			docs[i] = p.expr(operand)
+		}
+		return join(text(" "+e.Operator+" "), docs)
+	}
+	rest := concat{}
+	for _, operand := range operands[1:] {
+		rest = append(rest, line, text(e.Operator+" "), p.expr(operand))
+	}
+	if !indented {
+		return grouped(p.expr(operands[0]), rest)
+	}
+	return grouped(p.expr(operands[0]), indent(rest))
+}
+
+// chain lays out member accesses and method calls. A chain of two calls
+// or more breaks before each call when it does not fit, but the first
+// call on a variable.
+func (p *printer) chain(e ast.Expr) doc {
+	var links []ast.Expr
+	head := e
+	for {
+		switch link := head.(type) {
+		case *ast.PropertyExpression:
+			links, head = append(links, link), link.Object
+			continue
+		case *ast.NullsafePropertyExpression:
+			links, head = append(links, link), link.Object
+			continue
+		case *ast.MethodCallExpression:
+			links, head = append(links, link), link.Object
+			continue
+		}
+		break
+	}
+
+	calls := 0
+	for _, link := range links {
+		if _, ok := link.(*ast.MethodCallExpression); ok {
+			calls++
+		}
+	}
+	_, attached := head.(*ast.Variable)
+	out := concat{p.expr(head)}
+	rest := concat{}
+	for i := len(links) - 1; i >= 0; i-- {
+		switch link := links[i].(type) {
+		case *ast.PropertyExpression:
+			rest = append(rest, text("->"), p.member(link.Property))
+		case *ast.NullsafePropertyExpression:
+			rest = append(rest, text("?->"), p.member(link.Property))
+		case *ast.MethodCallExpression:
+			operator := "->"
+			if link.Nullsafe {
+				operator = "?->"
+			}
+			if calls >= 2 && !attached {
+				rest = append(rest, softline)
+			}
+			attached = false
+			rest = append(rest, text(operator), p.member(link.Method), p.arguments(link.Arguments, link.End()))
+		}
+	}
+	if calls < 2 {
+		return append(out, rest...)
+	}
+	return grouped(out, indent(rest))
+}
+
+// closure lays out an anonymous function. Its body always opens on the
+// line of its signature.
+func (p *printer) closure(e *ast.ClosureExpression) doc {
+	head := concat{p.inlineAttributes(e.Attributes)}
+	if e.Static {
+		head = append(head, text("static "))
+	}
+	head = append(head, text("function "+reference(e.ByRef)))
+
+	end := e.Body.Pos()
+	if e.ReturnType != nil {
+		end = e.ReturnType.Pos()
+	}
+	suffix := concat{}
+	if len(e.Use) > 0 {
+		end = e.Use[0].Variable.Pos()
+		uses := make([]item, len(e.Use))
+		for i, u := range e.Use {
+			u := u
+			uses[i] = item{pos: u.Variable.Pos(), end: u.Variable.End(), print: func() doc {
+				if u.ByRef {
+					return cat(text("&"), p.expr(u.Variable))
+				}
+				return p.expr(u.Variable)
+			}}
+		}
+		useEnd := e.Body.Pos()
+		if e.ReturnType != nil {
+			useEnd = e.ReturnType.Pos()
+		}
+		suffix = append(suffix, text(" use "), p.list("(", ")", uses, useEnd, listStyle{}, nil))
+	}
+	if e.ReturnType != nil {
+		suffix = append(suffix, text(": "), p.expr(e.ReturnType))
+	}
+	suffix = append(suffix, text(" "))
+	return cat(head, p.parameters(e.Parameters, end, suffix), p.braces(p.statements(e.Body.Statements, e.Body.End())))
+}
+
+// match lays out a match expression, one arm per line
+func (p *printer) match(e *ast.MatchExpression) doc {
+	arms := make([]entry, len(e.Arms))
+	for i, arm := range e.Arms {
+		arm := arm
+		pos := arm.Body.Pos()
+		if len(arm.Conditions) > 0 {
+			pos = arm.Conditions[0].Pos()
+		}
+		arms[i] = entry{pos: pos, end: arm.Body.End(), print: func() doc {
+			conditions := text("default")
+			if !arm.IsDefault {
+				docs := make([]doc, len(arm.Conditions))
+				for i, c := range arm.Conditions {
+					docs[i] = p.expr(c)
+				}
+				return cat(join(text(", "), docs), text(" => "), p.expr(arm.Body), text(","))
+			}
+			return cat(conditions, text(" => "), p.expr(arm.Body), text(","))
+		}}
+	}
+	header := cat(text("match "), p.condition(e.Subject), text(" "))
+	return cat(header, p.braces(p.sequence(arms, e.End())))
+}
@@ -0,0 +1,142 @@
+// Package format pretty-prints PHP source in the PSR-12 coding style. The
+// source is parsed and printed again from its AST: layout is normalized
+// while literals keep their source text and comments are kept in place
+// between statements, list items and members.
+package format
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/lexer"
+	"github.com/krizos/php-go/pkg/parser"
+)
+
+// BraceStyle is the placement of opening braces
+type BraceStyle int
+
+const (
+	// BracesPSR12 opens the bodies of class-likes, functions and methods
+	// on their own line, and those of control structures and closures at
+	// the end of the line
+	BracesPSR12 BraceStyle = iota
+	// BracesSameLine opens every body at the end of the line
+	BracesSameLine
+	// BracesNextLine opens every body but those of closures on its own line
+	BracesNextLine
+)
+
+// Options configure the layout
+type Options struct {
+	Indent     string // Indentation of a level: spaces or a tab
+	LineLength int    // Length past which lists, chains and operations break
+	Braces     BraceStyle
+}
+
+// DefaultOptions returns the PSR-12 layout: four spaces, 120 columns
+func DefaultOptions() Options {
+	return Options{Indent: "    ", LineLength: 120, Braces: BracesPSR12}
+}
+
+// ErrInlineHTML is returned for files starting with text outside of PHP
+// tags, which the parser does not represent. A closing tag is a parse
+// error.
+var ErrInlineHTML = errors.New("files with inline HTML or closing tags are not supported")
+
+// ErrUnsupported is returned for files with code the parser skips, such as
+// the constants of interfaces: printing the AST would drop it
+var ErrUnsupported = errors.New("the file has code the formatter does not support")
+
+// Source formats the PHP source of a file
+func Source(src []byte, filename string, opts Options) ([]byte, error) {
+	source := string(src)
+	if !strings.HasPrefix(source, "<?php") {
+		return nil, ErrInlineHTML
+	}
+
+	p := parser.New(lexer.New(source, filename))
+	program := p.ParseProgram()
+	if p.HasErrors() {
+		return nil, fmt.Errorf("%s", strings.Join(p.Errors(), "\n"))
+	}
+
+	if opts.Indent == "" {
+		opts.Indent = DefaultOptions().Indent
+	}
+	if opts.LineLength <= 0 {
+		opts.LineLength = DefaultOptions().LineLength
+	}
+	pr := &printer{opts: opts, src: source, comments: p.Comments()}
+	out := render(pr.program(program), opts.Indent, opts.LineLength)
+
+	// The output must be the same program: a bug of the printer must not
+	// lose code
+	formatted := parser.New(lexer.New(out, filename))
+	result := formatted.ParseProgram()
+	if formatted.HasErrors() || fingerprint(result) != fingerprint(program) || len(formatted.Comments()) != len(p.Comments()) {
+		return nil, fmt.Errorf("%s: formatting would change the program", filename)
+	}
+	if operands(out) != operands(source) {
+		return nil, ErrUnsupported
+	}
+	return []byte(out), nil
+}
+
+// operands lists the variables and literals of a source in order, which
+// formatting keeps
+func operands(source string) string {
+	var b strings.Builder
+	l := lexer.New(source, "")
+	for tok := l.NextToken(); tok.Type != lexer.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case lexer.INTEGER, lexer.FLOAT, lexer.STRING, lexer.HEREDOC, lexer.NOWDOC, lexer.ENCAPSED_START, lexer.VARIABLE:
+			b.WriteString(tok.Literal + "\x00")
+		}
+	}
+	return b.String()
+}
+
+// fingerprint describes a program without positions, tokens or layout:
+// programs with the same fingerprint are the same program
+func fingerprint(program *ast.Program) string {
+	var b strings.Builder
+	describe(&b, reflect.ValueOf(program))
+	return b.String()
+}
+
+func describe(b *strings.Builder, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			b.WriteString("nil ")
+			return
+		}
+		if v.Kind() == reflect.Interface {
+			b.WriteString(v.Elem().Type().String() + " ")
+		}
+		describe(b, v.Elem())
+	case reflect.Struct:
+		t := v.Type()
+		b.WriteString("{")
+		for i := 0; i < t.NumField(); i++ {
+			switch t.Field(i).Name {
+			case "Span", "Token", "DocComment":
+				continue
+			}
+			b.WriteString(t.Field(i).Name + ":")
+			describe(b, v.Field(i))
+		}
+		b.WriteString("} ")
+	case reflect.Slice:
+		fmt.Fprintf(b, "[%d ", v.Len())
+		for i := 0; i < v.Len(); i++ {
+			describe(b, v.Index(i))
+		}
+		b.WriteString("] ")
+	default:
+		fmt.Fprintf(b, "%#v ", v.Interface())
+	}
+}
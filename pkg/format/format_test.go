@@ -0,0 +1,212 @@
+package format
+
+import (
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/lexer"
+	"github.com/krizos/php-go/pkg/parser"
+)
+
+func format(t *testing.T, src string, opts Options) string {
+	t.Helper()
+	out, err := Source([]byte(src), "test.php", opts)
+	if err != nil {
+		t.Fatalf("Source: %v", err)
+	}
+	return string(out)
+}
+
+func TestSource_Layout(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"statements",
+			"<?php\n$a=1;$b  =  2;\n\n\n\necho $a,$b;",
+			"<?php\n\n$a = 1;\n$b = 2;\n\necho $a, $b;\n",
+		},
+		{
+			"control structures",
+			"<?php if($a){echo 1;}elseif($b){echo 2;}else{echo 3;} foreach($xs as $k=>&$v){$v++;} do{$i--;}while($i>0);",
+			"<?php\n\nif ($a) {\n    echo 1;\n} elseif ($b) {\n    echo 2;\n} else {\n    echo 3;\n}\n" +
+				"foreach ($xs as $k => &$v) {\n    $v++;\n}\ndo {\n    $i--;\n} while ($i > 0);\n",
+		},
+		{
+			"switch and try",
+			"<?php switch($x){case 1:case 2: f(); break; default: g();} try{f();}catch(A|B $e){}finally{g();}",
+			"<?php\n\nswitch ($x) {\n    case 1:\n    case 2:\n        f();\n        break;\n    default:\n        g();\n}\n" +
+				"try {\n    f();\n} catch (A | B $e) {\n} finally {\n    g();\n}\n",
+		},
+		{
+			"class",
+			"<?php namespace App; use A\\B; use C; abstract class Foo extends Bar implements Baz { use T; const X=1; protected static ?int $n=null;\n" +
+				"public function __construct(int $a, ...$rest){$this->a=$a;} abstract public function run(): void; }",
+			"<?php\n\nnamespace App;\n\nuse A\\B;\nuse C;\n\nabstract class Foo extends Bar implements Baz\n{\n    use T;\n\n" +
+				"    public const X = 1;\n    protected static ?int $n = null;\n\n    public function __construct(int $a, ...$rest)\n    {\n" +
+				"        $this->a = $a;\n    }\n\n    abstract public function run(): void;\n}\n",
+		},
+		{
+			"interface",
+			"<?php interface I extends J { const A=1; public function f(); #[Attr] public const B=2; }",
+			"<?php\n\ninterface I extends J\n{\n    public const A = 1;\n\n    public function f();\n\n" +
+				"    #[Attr]\n    public const B = 2;\n}\n",
+		},
+		{
+			"closures",
+			"<?php $f=function($x)use(&$y):int{return $x+$y;}; $g=fn($x)=>$x*2; usort($a,function($x,$y){return $x<=>$y;});",
+			"<?php\n\n$f = function ($x) use (&$y): int {\n    return $x + $y;\n};\n$g = fn ($x) => $x * 2;\n" +
+				"usort($a, function ($x, $y) {\n    return $x <=> $y;\n});\n",
+		},
+		{
+			"match",
+			"<?php $r=match($x){1,2=>'a',default=>'b'};",
+			"<?php\n\n$r = match ($x) {\n    1, 2 => 'a',\n    default => 'b',\n};\n",
+		},
+		{
+			"echo lists",
+			"<?php echo match($x){1=>'a',default=>'b'};echo match($x){1=>'a',default=>'b'},'c';echo 'x',function(){return 1;};",
+			"<?php\n\necho match ($x) {\n    1 => 'a',\n    default => 'b',\n};\necho match ($x) {\n    1 => 'a',\n    default => 'b',\n},\n    'c';\n" +
+				"echo 'x',\n    function () {\n        return 1;\n    };\n",
+		},
+		{
+			"normalizations",
+			"<?php $o=new Foo; $i=(int)$s; $n=- -1; $v=$o->{'a'.'b'};",
+			"<?php\n\n$o = new Foo();\n$i = (int) $s;\n$n = - -1;\n$v = $o->{'a' . 'b'};\n",
+		},
+		{
+			"literals kept",
+			"<?php $s = \"a $b {$c['d']}\"; $h = <<<EOT\n  text $x\n  EOT;\n$n = 0x1F + 1_000;",
+			"<?php\n\n$s = \"a $b {$c['d']}\";\n$h = <<<EOT\n  text $x\n  EOT;\n$n = 0x1F + 1_000;\n",
+		},
+		{
+			"comments",
+			"<?php\n// leading\n$a = 1; // trailing\n\n/**\n   * Doc\n   */\nfunction f() {\n  # inside\n}\n$x = [\n  1, // one\n  2,\n];\n// last",
+			"<?php\n\n// leading\n$a = 1; // trailing\n\n/**\n * Doc\n */\nfunction f()\n{\n    # inside\n}\n\n" +
+				"$x = [\n    1, // one\n    2,\n];\n// last\n",
+		},
+		{
+			"long lines",
+			"<?php $result = someFunction($firstArgument, $secondArgument, $thirdArgument, $fourthArgument, $fifthArgument);\n" +
+				"$s = $first . $second . $third . $fourth . $fifth . $sixth . $seventh . $eighth . $ninth . $tenth . $eleventh;\n" +
+				"if ($someCondition && $anotherCondition && $yetAnotherCondition && $oneMoreCondition && $lastConditionHere) {}\n" +
+				"$q = $db->table('users')->where('active', 1)->orderBy('name')->limit(10)->offset(20)->get();\n" +
+				"function f(int $firstParameter, string $secondParameter, array $thirdParameter = [], ?object $fourth = null): array {}",
+			"<?php\n\n$result = someFunction(\n    $firstArgument,\n    $secondArgument,\n    $thirdArgument,\n    $fourthArgument,\n    $fifthArgument,\n);\n" +
+				"$s = $first\n    . $second\n    . $third\n    . $fourth\n    . $fifth\n    . $sixth\n    . $seventh\n    . $eighth\n    . $ninth\n    . $tenth\n    . $eleventh;\n" +
+				"if (\n    $someCondition\n    && $anotherCondition\n    && $yetAnotherCondition\n    && $oneMoreCondition\n    && $lastConditionHere\n) {\n}\n" +
+				"$q = $db->table('users')\n    ->where('active', 1)\n    ->orderBy('name')\n    ->limit(10)\n    ->offset(20)\n    ->get();\n\n" +
+				"function f(\n    int $firstParameter,\n    string $secondParameter,\n    array $thirdParameter = [],\n    ?object $fourth = null\n): array {\n}\n",
+		},
+	}
+
+	opts := DefaultOptions()
+	opts.LineLength = 80
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := format(t, tt.input, opts); got != tt.expected {
+				t.Errorf("Expected\n%s\ngot\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSource_Options(t *testing.T) {
+	src := "<?php class A { function f($x) { if ($x) { $g = function () { return 1; }; } } }"
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{
+			"same line tabs",
+			Options{Indent: "\t", Braces: BracesSameLine},
+			"<?php\n\nclass A {\n\tpublic function f($x) {\n\t\tif ($x) {\n\t\t\t$g = function () {\n\t\t\t\treturn 1;\n\t\t\t};\n\t\t}\n\t}\n}\n",
+		},
+		{
+			"next line two spaces",
+			Options{Indent: "  ", Braces: BracesNextLine},
+			"<?php\n\nclass A\n{\n  public function f($x)\n  {\n    if ($x)\n    {\n      $g = function () {\n        return 1;\n      };\n    }\n  }\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := format(t, src, tt.opts); got != tt.expected {
+				t.Errorf("Expected\n%s\ngot\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSource_Errors(t *testing.T) {
+	if _, err := Source([]byte("<?php $a = ;"), "test.php", DefaultOptions()); err == nil {
+		t.Error("Expected a parse error")
+	}
+	if _, err := Source([]byte("<html><?php echo 1;"), "test.php", DefaultOptions()); err != ErrInlineHTML {
+		t.Errorf("Expected ErrInlineHTML, got %v", err)
+	}
+}
+
+// corpus returns the PHP programs of the repository's tests: the string
+// literals of its Go test files starting with <?php
+func corpus(t *testing.T) []string {
+	t.Helper()
+	var programs []string
+	fset := token.NewFileSet()
+	filepath.WalkDir("..", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		file, err := goparser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			if lit, ok := n.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				if s, err := strconv.Unquote(lit.Value); err == nil && strings.HasPrefix(s, "<?php") {
+					programs = append(programs, s)
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	return programs
+}
+
+// TestSource_Idempotent formats the programs of the test corpus twice:
+// formatting formatted code must not change it
+func TestSource_Idempotent(t *testing.T) {
+	formatted := 0
+	for _, src := range corpus(t) {
+		p := parser.New(lexer.New(src, "test.php"))
+		p.ParseProgram()
+		if p.HasErrors() || strings.Contains(src, "?>") {
+			continue // Tests of errors and inline HTML
+		}
+		once, err := Source([]byte(src), "test.php", DefaultOptions())
+		if err == ErrUnsupported {
+			continue
+		}
+		if err != nil {
+			t.Errorf("Formatting\n%s\nfailed: %v", src, err)
+			continue
+		}
+		twice, err := Source(once, "test.php", DefaultOptions())
+		if err != nil || string(twice) != string(once) {
+			t.Errorf("Formatting\n%s\nagain gave\n%s\n(%v)", once, twice, err)
+		}
+		formatted++
+	}
+	if formatted < 100 {
+		t.Errorf("Expected a corpus of at least 100 programs, formatted %d", formatted)
+	}
+}
@@ -0,0 +1,609 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+// ============================================================================
+// Printer
+// ============================================================================
+
+// printer builds the document of a program. Comments are not part of the
+// AST: they are taken from the parser in source order, each printed before
+// the first statement, member or list item starting after it.
+type printer struct {
+	opts     Options
+	src      string
+	comments []lexer.Token
+	next     int  // Index of the first comment not printed yet
+	inTrait  bool // The parser reads the constants of traits without visibility
+}
+
+// program lays out a file
+func (p *printer) program(program *ast.Program) doc {
+	body := p.statements(program.Statements, program.End())
+	if len(body) == 0 {
+		return cat(text("<?php"), hardline)
+	}
+	return cat(text("<?php"), blankline, body, hardline)
+}
+
+// source returns the source text of a node
+func (p *printer) source(node ast.Node) string {
+	start, end := node.Pos().Offset, node.End().Offset
+	if start < 0 || end > len(p.src) || start >= end {
+		return node.TokenLiteral()
+	}
+	return p.src[start:end]
+}
+
+// ============================================================================
+// Comments
+// ============================================================================
+
+// commentsBefore returns the comments not printed yet that start before a
+// position, which are printed by the caller
+func (p *printer) commentsBefore(pos lexer.Position) []lexer.Token {
+	start := p.next
+	for p.next < len(p.comments) && p.comments[p.next].Pos.Offset < pos.Offset {
+		p.next++
+	}
+	return p.comments[start:p.next]
+}
+
+// comment lays out a comment. Line comments break their group: what
+// follows them must start a new line. The lines of block comments starting
+// with * are aligned on the first; others are kept as written.
+func (p *printer) comment(c lexer.Token) doc {
+	literal := strings.TrimRight(c.Literal, " \t\r\n")
+	if !strings.HasPrefix(literal, "/*") {
+		return cat(text(literal), breakParent{})
+	}
+	lines := strings.Split(literal, "\n")
+	for _, l := range lines[1:] {
+		if !strings.HasPrefix(strings.TrimSpace(l), "*") {
+			return text(literal)
+		}
+	}
+	out := concat{text(strings.TrimRight(lines[0], " \t\r"))}
+	for _, l := range lines[1:] {
+		out = append(out, hardline, text(" "+strings.TrimRight(strings.TrimSpace(l), "\r")))
+	}
+	return out
+}
+
+// trailing splits off the comments starting on the line a previous
+// element ends on, which follow it on its line
+func trailing(comments []lexer.Token, prevEnd lexer.Position) (trail, rest []lexer.Token) {
+	n := 0
+	for n < len(comments) && prevEnd.IsValid() && comments[n].Pos.Line == prevEnd.Line {
+		n++
+	}
+	return comments[:n], comments[n:]
+}
+
+// trailingDocs lays out trailing comments, each preceded by a space
+func (p *printer) trailingDocs(comments []lexer.Token) doc {
+	out := concat{}
+	for _, c := range comments {
+		out = append(out, text(" "), p.comment(c))
+	}
+	return out
+}
+
+// ============================================================================
+// Sequences
+// ============================================================================
+
+// entry kinds, which decide the blank lines around an entry
+const (
+	plainEntry  = iota
+	spacedEntry // Functions, class-likes and methods: a blank line around
+	useEntry    // Use statements: a blank line after a run of them
+	headerEntry // Namespace and declare statements: a blank line after
+)
+
+// entry is a statement or member of a sequence
+type entry struct {
+	pos, end lexer.Position
+	kind     int
+	print    func() doc
+}
+
+// sequence lays out entries one per line with the comments between them.
+// Blank lines of the source are kept, one at most, and added around
+// declarations; comments left before end close the sequence.
+func (p *printer) sequence(entries []entry, end lexer.Position) concat {
+	out := concat{}
+	var prev *entry
+	for i := range entries {
+		e := &entries[i]
+		comments := p.commentsBefore(e.pos)
+		if prev != nil {
+			var trail []lexer.Token
+			trail, comments = trailing(comments, prev.end)
+			out = append(out, p.trailingDocs(trail), hardline)
+			first := e.pos.Line
+			if len(comments) > 0 {
+				first = comments[0].Pos.Line
+			}
+			if first > prev.end.Line+1 || blankBetween(prev.kind, e.kind) {
+				out = append(out, hardline)
+			}
+		}
+		for j, c := range comments {
+			out = append(out, p.comment(c), hardline)
+			next := e.pos.Line
+			if j+1 < len(comments) {
+				next = comments[j+1].Pos.Line
+			}
+			if next > c.End.Line+1 {
+				out = append(out, hardline)
+			}
+		}
+		out = append(out, e.print())
+		prev = e
+	}
+
+	comments := p.commentsBefore(end)
+	last := lexer.Position{}
+	if prev != nil {
+		var trail []lexer.Token
+		trail, comments = trailing(comments, prev.end)
+		out = append(out, p.trailingDocs(trail))
+		last = prev.end
+	}
+	for _, c := range comments {
+		if len(out) > 0 {
+			out = append(out, hardline)
+			if c.Pos.Line > last.Line+1 {
+				out = append(out, hardline)
+			}
+		}
+		out = append(out, p.comment(c))
+		last = c.End
+	}
+	return out
+}
+
+// blankBetween reports whether entries of two kinds are separated by a
+// blank line
+func blankBetween(prev, next int) bool {
+	return prev == spacedEntry || next == spacedEntry || prev == headerEntry ||
+		prev == useEntry && next != useEntry
+}
+
+// statements lays out a statement list
+func (p *printer) statements(stmts []ast.Stmt, end lexer.Position) concat {
+	entries := make([]entry, len(stmts))
+	for i, stmt := range stmts {
+		stmt := stmt
+		entries[i] = entry{pos: stmt.Pos(), end: stmt.End(), kind: statementKind(stmt), print: func() doc { return p.statement(stmt) }}
+	}
+	return p.sequence(entries, end)
+}
+
+// statementKind returns the entry kind of a statement
+func statementKind(stmt ast.Stmt) int {
+	switch stmt := stmt.(type) {
+	case *ast.FunctionDeclaration, *ast.ClassDeclaration, *ast.InterfaceDeclaration,
+		*ast.TraitDeclaration, *ast.EnumDeclaration, *ast.MethodDeclaration:
+		return spacedEntry
+	case *ast.UseStatement, *ast.TraitUse:
+		return useEntry
+	case *ast.NamespaceStatement:
+		if stmt.Body == nil {
+			return headerEntry
+		}
+		return spacedEntry
+	case *ast.DeclareStatement:
+		if stmt.Body == nil {
+			return headerEntry
+		}
+	}
+	return plainEntry
+}
+
+// ============================================================================
+// Lists
+// ============================================================================
+
+// item is an element of a list
+type item struct {
+	pos, end lexer.Position
+	print    func() doc
+}
+
+// listStyle configures the layout of a list
+type listStyle struct {
+	trailingComma bool // After the last item of a broken list
+	hug           bool // A last multi-line item may open on the line of the list
+}
+
+// list lays out items between delimiters: on one line if they fit, one per
+// line otherwise. A comment among the items breaks the list. suffix is
+// part of the group, so that it can depend on whether the list broke.
+func (p *printer) list(open, close string, items []item, end lexer.Position, style listStyle, suffix doc) doc {
+	if len(items) == 0 {
+		comments := p.commentsBefore(end)
+		if len(comments) == 0 {
+			return grouped(text(open+close), suffix)
+		}
+		inner := concat{}
+		for _, c := range comments {
+			inner = append(inner, hardline, p.comment(c))
+		}
+		return grouped(text(open), indent(inner), hardline, text(close), suffix)
+	}
+
+	docs := make([]doc, len(items))
+	body := concat{softline}
+	commented := false
+	var prevEnd lexer.Position
+	for i, it := range items {
+		comments := p.commentsBefore(it.pos)
+		if i > 0 {
+			var trail []lexer.Token
+			trail, comments = trailing(comments, prevEnd)
+			body = append(body, text(","), p.trailingDocs(trail), line)
+			commented = commented || len(trail) > 0
+		}
+		for _, c := range comments {
+			body = append(body, p.comment(c), hardline)
+			commented = true
+		}
+		docs[i] = it.print()
+		body = append(body, docs[i])
+		prevEnd = it.end
+	}
+	comments := p.commentsBefore(end)
+	if style.trailingComma {
+		body = append(body, ifBreak{broken: text(","), flat: text("")})
+	}
+	trail, comments := trailing(comments, prevEnd)
+	body = append(body, p.trailingDocs(trail))
+	for _, c := range comments {
+		body = append(body, hardline, p.comment(c))
+	}
+	if commented || len(trail)+len(comments) > 0 {
+		body = append(body, breakParent{})
+	}
+
+	if style.hug && huggable(docs) && !hasBreakParent(body) {
+		return cat(text(open), join(text(", "), docs), text(close), grouped(suffix))
+	}
+	return grouped(text(open), indent(body), softline, text(close), suffix)
+}
+
+// huggable reports whether the last of list items is the only one
+// spanning several lines, as a closure or array does: the list then opens
+// and closes on the lines of the item
+func huggable(docs []doc) bool {
+	for _, d := range docs[:len(docs)-1] {
+		if hasHard(d) {
+			return false
+		}
+	}
+	return hasHard(docs[len(docs)-1])
+}
+
+// hasBreakParent reports whether a document contains a comment breaking
+// its group
+func hasBreakParent(d doc) bool {
+	switch d := d.(type) {
+	case breakParent:
+		return true
+	case concat:
+		for _, part := range d {
+			if hasBreakParent(part) {
+				return true
+			}
+		}
+	case nest:
+		return hasBreakParent(d.doc)
+	case *group:
+		return hasBreakParent(d.doc)
+	}
+	return false
+}
+
+// exprItems returns the list items of expressions
+func (p *printer) exprItems(exprs []ast.Expr) []item {
+	items := make([]item, len(exprs))
+	for i, e := range exprs {
+		e := e
+		items[i] = item{pos: e.Pos(), end: e.End(), print: func() doc { return p.expr(e) }}
+	}
+	return items
+}
+
+// arguments lays out the arguments of a call
+func (p *printer) arguments(args []ast.Expr, end lexer.Position) doc {
+	return p.list("(", ")", p.exprItems(args), end, listStyle{trailingComma: true, hug: true}, nil)
+}
+
+// ============================================================================
+// Bodies
+// ============================================================================
+
+// opening returns what precedes the opening brace of a body: a space, or a
+// line break for the bodies the brace style opens on their own line
+func (p *printer) opening(declaration bool) doc {
+	if p.opts.Braces == BracesNextLine || declaration && p.opts.Braces == BracesPSR12 {
+		return hardline
+	}
+	return text(" ")
+}
+
+// continuation returns what separates the closing brace of a body from
+// the else, catch or while continuing its statement
+func (p *printer) continuation() doc {
+	if p.opts.Braces == BracesNextLine {
+		return hardline
+	}
+	return text(" ")
+}
+
+// braces lays out statements between braces
+func (p *printer) braces(body concat) doc {
+	if len(body) == 0 {
+		return cat(text("{"), hardline, text("}"))
+	}
+	return cat(text("{"), indent(hardline, body), hardline, text("}"))
+}
+
+// block lays out a block after its opening
+func (p *printer) block(open doc, block *ast.BlockStatement) doc {
+	return cat(open, p.braces(p.statements(block.Statements, block.End())))
+}
+
+// ============================================================================
+// Statements
+// ============================================================================
+
+// statement lays out a statement
+func (p *printer) statement(stmt ast.Stmt) doc {
+	switch s := stmt.(type) {
+	case *ast.ExpressionStatement:
+		return cat(p.expr(s.Expression), text(";"))
+	case *ast.BlockStatement:
+		return p.block(nil, s)
+	case *ast.EchoStatement:
+		return cat(text("echo "), p.commaList(s.Expressions), text(";"))
+	case *ast.ReturnStatement:
+		if s.ReturnValue == nil {
+			return text("return;")
+		}
+		return cat(text("return "), p.expr(s.ReturnValue), text(";"))
+	case *ast.BreakStatement:
+		return p.jump("break", s.Depth)
+	case *ast.ContinueStatement:
+		return p.jump("continue", s.Depth)
+	case *ast.ThrowStatement:
+		return cat(text("throw "), p.expr(s.Expression), text(";"))
+	case *ast.IfStatement:
+		return p.ifStatement(s)
+	case *ast.WhileStatement:
+		return p.block(cat(text("while "), p.condition(s.Condition), p.opening(false)), s.Body)
+	case *ast.DoWhileStatement:
+		return cat(p.block(text("do "), s.Body), p.continuation(), text("while "), p.condition(s.Condition), text(";"))
+	case *ast.ForStatement:
+		return p.forStatement(s)
+	case *ast.ForeachStatement:
+		return p.foreachStatement(s)
+	case *ast.SwitchStatement:
+		return p.switchStatement(s)
+	case *ast.TryStatement:
+		return p.tryStatement(s)
+	case *ast.StaticVarStatement:
+		vars := make([]doc, len(s.Vars))
+		for i, v := range s.Vars {
+			vars[i] = p.expr(v.Name)
+			if v.Value != nil {
+				vars[i] = cat(vars[i], text(" = "), p.expr(v.Value))
+			}
+		}
+		return cat(text("static "), join(text(", "), vars), text(";"))
+	case *ast.GlobalStatement:
+		vars := make([]ast.Expr, len(s.Vars))
+		for i, v := range s.Vars {
+			vars[i] = v
+		}
+		return cat(text("global "), p.commaList(vars), text(";"))
+	case *ast.ConstStatement:
+		return cat(text("const "), p.constants(s.Constants), text(";"))
+	case *ast.DeclareStatement:
+		directives := make([]doc, len(s.Directives))
+		for i, d := range s.Directives {
+			directives[i] = cat(text(d.Name+"="), p.expr(d.Value))
+		}
+		header := cat(text("declare("), join(text(", "), directives), text(")"))
+		if s.Body == nil {
+			return cat(header, text(";"))
+		}
+		return p.block(cat(header, p.opening(false)), s.Body)
+	case *ast.UnsetStatement:
+		return cat(text("unset"), p.list("(", ")", p.exprItems(s.Variables), s.End(), listStyle{}, nil), text(";"))
+	case *ast.NamespaceStatement:
+		header := "namespace"
+		if s.Name != "" {
+			header += " " + s.Name
+		}
+		if s.Body == nil {
+			return text(header + ";")
+		}
+		return p.block(cat(text(header), p.opening(true)), s.Body)
+	case *ast.UseStatement:
+		return p.useStatement(s)
+	}
+	return p.declaration(stmt)
+}
+
+// jump lays out a break or continue
+func (p *printer) jump(keyword string, depth ast.Expr) doc {
+	if depth == nil {
+		return text(keyword + ";")
+	}
+	return cat(text(keyword+" "), p.expr(depth), text(";"))
+}
+
+// commaList lays out expressions separated by commas, breaking after the
+// commas when they do not fit. Only the continuation lines are indented,
+// so a first expression spanning lines, such as a match, keeps the
+// indentation of the statement.
+func (p *printer) commaList(exprs []ast.Expr) doc {
+	if len(exprs) == 0 {
+		return concat{}
+	}
+	rest := concat{}
+	for _, e := range exprs[1:] {
+		rest = append(rest, text(","), line, p.expr(e))
+	}
+	return grouped(p.expr(exprs[0]), indent(rest))
+}
+
+// condition lays out the parenthesized condition of a control structure.
+// A condition too long for the line starts on the next one, and the
+// closing parenthesis on its own.
+func (p *printer) condition(cond ast.Expr) doc {
+	body := p.expr(cond)
+	if infix, ok := cond.(*ast.InfixExpression); ok {
+		body = p.infix(infix, false)
+	}
+	return grouped(text("("), indent(softline, body), softline, text(")"))
+}
+
+func (p *printer) ifStatement(s *ast.IfStatement) doc {
+	out := concat{p.block(cat(text("if "), p.condition(s.Condition), p.opening(false)), s.Consequence)}
+	for _, elseIf := range s.ElseIfs {
+		out = append(out, p.continuation(), p.block(cat(text("elseif "), p.condition(elseIf.Condition), p.opening(false)), elseIf.Consequence))
+	}
+	if s.Alternative != nil {
+		out = append(out, p.continuation(), p.block(cat(text("else"), p.opening(false)), s.Alternative))
+	}
+	return out
+}
+
+func (p *printer) forStatement(s *ast.ForStatement) doc {
+	parts := make([]doc, 0, 3)
+	for _, exprs := range [][]ast.Expr{s.Init, s.Condition, s.Increment} {
+		parts = append(parts, p.commaList(exprs))
+	}
+	header := cat(text("for ("), join(text("; "), parts), text(")"))
+	return p.block(cat(header, p.opening(false)), s.Body)
+}
+
+func (p *printer) foreachStatement(s *ast.ForeachStatement) doc {
+	header := concat{text("foreach ("), p.expr(s.Array), text(" as ")}
+	if s.Key != nil {
+		header = append(header, p.expr(s.Key), text(" => "))
+	}
+	if s.ByRef {
+		header = append(header, text("&"))
+	}
+	header = append(header, p.expr(s.Value), text(")"))
+	return p.block(cat(header, p.opening(false)), s.Body)
+}
+
+func (p *printer) switchStatement(s *ast.SwitchStatement) doc {
+	cases := make([]entry, len(s.Cases))
+	for i, c := range s.Cases {
+		c := c
+		end := s.End()
+		if i+1 < len(s.Cases) {
+			end = s.Cases[i+1].Token.Pos
+		}
+		last := c.Token.End
+		if n := len(c.Body); n > 0 {
+			last = c.Body[n-1].End()
+		}
+		cases[i] = entry{pos: c.Token.Pos, end: last, print: func() doc {
+			var label doc = text("default:")
+			if c.Value != nil {
+				label = cat(text("case "), p.expr(c.Value), text(":"))
+			}
+			body := p.statements(c.Body, end)
+			if len(body) == 0 {
+				return label
+			}
+			return cat(label, indent(hardline, body))
+		}}
+	}
+	header := cat(text("switch "), p.condition(s.Subject), p.opening(false))
+	return cat(header, p.braces(p.sequence(cases, s.End())))
+}
+
+func (p *printer) tryStatement(s *ast.TryStatement) doc {
+	out := concat{p.block(cat(text("try"), p.opening(false)), s.Body)}
+	for _, c := range s.CatchClauses {
+		types := make([]doc, len(c.Types))
+		for i, t := range c.Types {
+			types[i] = p.expr(t)
+		}
+		header := concat{text("catch ("), join(text(" | "), types)}
+		if c.Variable != nil {
+			header = append(header, text(" "), p.expr(c.Variable))
+		}
+		header = append(header, text(")"), p.opening(false))
+		out = append(out, p.continuation(), p.block(header, c.Body))
+	}
+	if s.Finally != nil {
+		out = append(out, p.continuation(), p.block(cat(text("finally"), p.opening(false)), s.Finally))
+	}
+	return out
+}
+
+// useStatement lays out an import. Imports of different kinds can only
+// share a statement in a group, which is printed with the longest common
+// namespace of the names.
+func (p *printer) useStatement(s *ast.UseStatement) doc {
+	kind := s.Items[0].Kind
+	mixed := false
+	for _, it := range s.Items {
+		mixed = mixed || it.Kind != kind
+	}
+
+	prefix := ""
+	if mixed {
+		prefix = s.Items[0].Name
+		for _, it := range s.Items {
+			for !strings.HasPrefix(it.Name, prefix+`\`) && prefix != "" {
+				prefix = prefix[:max(strings.LastIndex(prefix, `\`), 0)]
+			}
+		}
+	}
+
+	names := make([]doc, len(s.Items))
+	for i, it := range s.Items {
+		name := it.Name
+		if mixed {
+			name = strings.TrimPrefix(name, prefix+`\`)
+			if it.Kind != "" {
+				name = it.Kind + " " + name
+			}
+		}
+		if it.Alias != "" {
+			name += " as " + it.Alias
+		}
+		names[i] = text(name)
+	}
+	if mixed {
+		return cat(text("use "+prefix+`\{`), join(text(", "), names), text("};"))
+	}
+	if kind != "" {
+		return cat(text("use "+kind+" "), join(text(", "), names), text(";"))
+	}
+	return cat(text("use "), join(text(", "), names), text(";"))
+}
+
+// constants lays out the items of a constant declaration
+func (p *printer) constants(items []*ast.ConstantItem) doc {
+	docs := make([]doc, len(items))
+	for i, c := range items {
+		docs[i] = cat(text(c.Name.Value+" = "), p.expr(c.Value))
+	}
+	return join(text(", "), docs)
+}
@@ -0,0 +1,134 @@
+package fuzz
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/krizos/php-go/pkg/compiler"
+)
+
+// ============================================================================
+// Differential Testing
+// ============================================================================
+
+// Divergence is a program php-go runs differently from PHP, or that makes
+// php-go panic or hang
+type Divergence struct {
+	Program   string
+	Reason    string
+	PHPGo     Outcome
+	Reference *Outcome // nil without a reference interpreter
+}
+
+// String describes a divergence for reports
+func (d *Divergence) String() string {
+	report := fmt.Sprintf("%s\nphp-go:    %s\n", d.Reason, d.PHPGo)
+	if d.Reference != nil {
+		report += fmt.Sprintf("reference: %s\n", d.Reference)
+	}
+	return report
+}
+
+// Compare compares the outcomes of a program under php-go and PHP,
+// returning nil if they agree. A nil reference only checks that php-go
+// neither panicked nor hung.
+func Compare(program string, got Outcome, want *Outcome) *Divergence {
+	d := &Divergence{Program: program, PHPGo: got, Reference: want}
+	switch {
+	case got.Panic != "":
+		d.Reason = "php-go panicked"
+	case want == nil && got.TimedOut:
+		d.Reason = "php-go timed out"
+	case want == nil:
+		return nil
+	case got.TimedOut != want.TimedOut:
+		d.Reason = "only one interpreter timed out"
+	case got.TimedOut:
+		return nil
+	case got.ExitStatus != want.ExitStatus:
+		d.Reason = fmt.Sprintf("exit status %d, PHP exits with %d", got.ExitStatus, want.ExitStatus)
+	case got.Output != want.Output:
+		d.Reason = "the output differs"
+	default:
+		return nil
+	}
+	return d
+}
+
+// Options are the settings of a fuzzing campaign
+type Options struct {
+	Seed       int64
+	Iterations int
+	Timeout    time.Duration // For each run of a program
+	Corpus     []string      // Programs to mutate; programs are generated half of the time
+	Reference  *Reference    // nil to run php-go alone
+	Level      compiler.OptimizationLevel
+}
+
+// DefaultTimeout bounds each run of a program unless Options.Timeout is set
+const DefaultTimeout = 5 * time.Second
+
+// Stats counts the programs of a campaign
+type Stats struct {
+	Programs    int
+	Divergences int
+}
+
+// Run runs a campaign: the programs of the options' seed, generated or
+// mutated from the corpus, under php-go and the reference, calling report
+// with each divergence. It stops at the end of the iterations or of the
+// context, or when running the reference fails.
+func Run(ctx context.Context, opts Options, report func(*Divergence)) (Stats, error) {
+	var stats Stats
+	generator := NewGenerator(opts.Seed)
+	mutations := rand.New(rand.NewSource(opts.Seed))
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	for i := 0; i < opts.Iterations && ctx.Err() == nil; i++ {
+		program := generator.Program()
+		if len(opts.Corpus) > 0 && mutations.Intn(2) == 0 {
+			program = Mutate(opts.Corpus[mutations.Intn(len(opts.Corpus))], mutations)
+		}
+
+		d, err := check(ctx, program, opts, timeout)
+		if err != nil {
+			return stats, err
+		}
+		stats.Programs++
+		if d != nil {
+			stats.Divergences++
+			report(d)
+		}
+	}
+	return stats, nil
+}
+
+// check runs a program under both interpreters and compares the outcomes
+func check(ctx context.Context, program string, opts Options, timeout time.Duration) (*Divergence, error) {
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	got := RunPHPGo(runCtx, program, opts.Level)
+	cancel()
+	if ctx.Err() != nil {
+		return nil, nil // The campaign was stopped, not the program
+	}
+
+	var want *Outcome
+	if opts.Reference != nil {
+		runCtx, cancel := context.WithTimeout(ctx, timeout)
+		outcome, err := opts.Reference.Run(runCtx, program)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		want = &outcome
+	}
+	return Compare(program, got, want), nil
+}
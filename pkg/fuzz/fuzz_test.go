@@ -0,0 +1,160 @@
+package fuzz
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krizos/php-go/pkg/compiler"
+)
+
+func TestGenerator_Deterministic(t *testing.T) {
+	a, b := NewGenerator(42), NewGenerator(42)
+	for i := 0; i < 20; i++ {
+		program := a.Program()
+		if program != b.Program() {
+			t.Fatalf("Program %d differs between generators of the same seed", i)
+		}
+		if !strings.HasPrefix(program, "<?php\n") || !strings.HasSuffix(program, ";\n") && !strings.HasSuffix(program, "}\n") {
+			t.Errorf("Unexpected program:\n%s", program)
+		}
+	}
+	if NewGenerator(1).Program() == NewGenerator(2).Program() {
+		t.Error("Expected different seeds to generate different programs")
+	}
+}
+
+func TestMutate(t *testing.T) {
+	program := "<?php\n$v0 = (1 + 'abc');\necho $v0, \"\\n\";\n"
+	r := rand.New(rand.NewSource(1))
+	changed := 0
+	for i := 0; i < 50; i++ {
+		mutant := Mutate(program, r)
+		if !strings.HasPrefix(mutant, "<?php") {
+			t.Fatalf("The open tag should not be mutated: %q", mutant)
+		}
+		if mutant != program {
+			changed++
+		}
+	}
+	if changed == 0 {
+		t.Error("Expected mutations to change the program")
+	}
+
+	if got := Mutate("<?php /* comment */", r); got != "<?php /* comment */" {
+		t.Errorf("Expected a program without mutable tokens unchanged, got %q", got)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ok := Outcome{Output: "3\n"}
+	tests := []struct {
+		name   string
+		got    Outcome
+		want   *Outcome
+		reason string
+	}{
+		{"agree", ok, &ok, ""},
+		{"output", Outcome{Output: "4\n"}, &ok, "the output differs"},
+		{"status", Outcome{Output: "3\n", ExitStatus: 255}, &ok, "exit status 255, PHP exits with 0"},
+		{"panic", Outcome{Panic: "boom"}, nil, "php-go panicked"},
+		{"hang", Outcome{TimedOut: true}, nil, "php-go timed out"},
+		{"one timeout", Outcome{TimedOut: true}, &ok, "only one interpreter timed out"},
+		{"both timeout", Outcome{TimedOut: true}, &Outcome{TimedOut: true}, ""},
+		{"no reference", Outcome{Output: "anything"}, nil, ""},
+	}
+	for _, tt := range tests {
+		d := Compare("<?php", tt.got, tt.want)
+		switch {
+		case tt.reason == "" && d != nil:
+			t.Errorf("%s: unexpected divergence %s", tt.name, d)
+		case tt.reason != "" && (d == nil || d.Reason != tt.reason):
+			t.Errorf("%s: expected divergence %q, got %v", tt.name, tt.reason, d)
+		}
+	}
+}
+
+func TestRunPHPGo(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if outcome := RunPHPGo(ctx, "<?php echo 1 + 2, \"\\n\";", compiler.OptimizeNone); outcome.Output != "3\n" || outcome.ExitStatus != 0 {
+		t.Errorf("Expected output \"3\\n\" and status 0, got %s", outcome)
+	}
+	if outcome := RunPHPGo(ctx, "<?php echo (;", compiler.OptimizeNone); outcome.ExitStatus != fatalStatus {
+		t.Errorf("Expected a parse error to exit with 255, got %s", outcome)
+	}
+}
+
+func TestRun_WithoutReference(t *testing.T) {
+	var reported []*Divergence
+	stats, err := Run(context.Background(), Options{Seed: 3, Iterations: 10, Timeout: time.Second}, func(d *Divergence) {
+		reported = append(reported, d)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Programs != 10 || stats.Divergences != len(reported) {
+		t.Errorf("Expected 10 programs and a report per divergence, got %+v and %d reports", stats, len(reported))
+	}
+	for _, d := range reported {
+		if d.Reference != nil || d.PHPGo.Panic == "" && !d.PHPGo.TimedOut {
+			t.Errorf("Without a reference only panics and hangs diverge, got %s", d)
+		}
+	}
+}
+
+func TestReference_Run(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake php binary is a shell script")
+	}
+	// The fake php prints the first line of the program it runs
+	path := filepath.Join(t.TempDir(), "php")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nfor last; do :; done\nhead -n 1 \"$last\"\nexit 3\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PHP_BINARY", path)
+	reference, ok := FindReference()
+	if !ok || reference.Path != path {
+		t.Fatalf("Expected PHP_BINARY to name the reference, got %v", reference)
+	}
+
+	outcome, err := reference.Run(context.Background(), "<?php\necho 1;\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if outcome.Output != "<?php\n" || outcome.ExitStatus != 3 {
+		t.Errorf("Expected the program's first line and status 3, got %s", outcome)
+	}
+}
+
+// FuzzDifferential runs the fuzzed programs under php-go and the php
+// binary, failing on divergences. It needs PHP, found as FindReference
+// does.
+func FuzzDifferential(f *testing.F) {
+	reference, ok := FindReference()
+	if !ok {
+		f.Skip("no php binary for differential fuzzing; set PHP_BINARY")
+	}
+	for _, seed := range []string{
+		"<?php echo 1 + 2, \"\\n\";",
+		"<?php $a = 'x'; echo $a . 'y', \"\\n\";",
+		"<?php echo 7 % 3, PHP_EOL;",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, program string) {
+		d, err := check(context.Background(), program, Options{Reference: reference}, DefaultTimeout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d != nil {
+			t.Errorf("%s\n%s", program, d)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+// Package fuzz tests php-go against the reference PHP interpreter. It
+// generates small PHP programs, or mutates programs of a corpus, runs each
+// under php-go and, when a php binary is available, under PHP, and reports
+// the programs whose output or exit status differ. Without PHP it still
+// reports the programs that make php-go panic or hang.
+//
+// The lexer and parser have native Go fuzz tests of their own, which
+// check that malformed input never makes them panic.
+package fuzz
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// ============================================================================
+// Program Generation
+// ============================================================================
+
+// Generator produces random PHP programs: assignments, echo and var_dump
+// statements and if/else blocks over expressions of literals, variables
+// and operators. The programs of a seed are always the same.
+type Generator struct {
+	rand  *rand.Rand
+	vars  int // Variables assigned so far: $v0 to $v<vars-1>
+	depth int // Nesting of the expression being generated
+}
+
+// Limits of generated programs
+const (
+	maxStatements = 8
+	maxDepth      = 3
+)
+
+// Literal values generation draws from: boundaries and the strings PHP
+// converts in surprising ways
+var (
+	intLiterals    = []string{"0", "1", "-1", "2", "7", "10", "255", "-128", "PHP_INT_MAX", "PHP_INT_MIN", "9223372036854775807"}
+	floatLiterals  = []string{"0.0", "-0.0", "0.1", "1.5", "-2.5", "1e3", "1.0E+25", "0.30000000000000004", "NAN", "INF"}
+	stringLiterals = []string{`""`, `"0"`, `"1"`, `"abc"`, `"10"`, `"1e3"`, `" 5"`, `"5 "`, `"0x1A"`, `"-0"`, `"null"`, `"\n"`}
+	otherLiterals  = []string{"true", "false", "null"}
+
+	binaryOperators = []string{"+", "-", "*", "/", "%", "**", ".", "==", "===", "!=", "!==", "<", "<=", ">", ">=", "<=>", "&&", "||", "xor", "??", "&", "|", "^", "<<", ">>"}
+	unaryOperators  = []string{"-", "+", "!", "~", "(int)", "(float)", "(string)", "(bool)"}
+)
+
+// NewGenerator returns a generator of the programs of a seed
+func NewGenerator(seed int64) *Generator {
+	return &Generator{rand: rand.New(rand.NewSource(seed))}
+}
+
+// Program returns the next program
+func (g *Generator) Program() string {
+	g.vars = 0
+	var b strings.Builder
+	b.WriteString("<?php\n")
+	for i, n := 0, 1+g.rand.Intn(maxStatements); i < n; i++ {
+		g.statement(&b, "")
+	}
+	return b.String()
+}
+
+// statement writes a statement
+func (g *Generator) statement(b *strings.Builder, indent string) {
+	switch n := g.rand.Intn(10); {
+	case n < 4 || g.vars == 0:
+		value := g.expression() // Before the variable it is assigned to exists
+		fmt.Fprintf(b, "%s$v%d = %s;\n", indent, g.assignee(), value)
+	case n < 7:
+		fmt.Fprintf(b, "%secho %s, \"\\n\";\n", indent, g.expression())
+	case n < 9:
+		fmt.Fprintf(b, "%svar_dump(%s);\n", indent, g.expression())
+	default:
+		if indent != "" {
+			fmt.Fprintf(b, "%secho %s, \"\\n\";\n", indent, g.expression())
+			return
+		}
+		fmt.Fprintf(b, "if (%s) {\n", g.expression())
+		g.statement(b, "    ")
+		b.WriteString("} else {\n")
+		g.statement(b, "    ")
+		b.WriteString("}\n")
+	}
+}
+
+// assignee returns the number of the variable an assignment sets: a new
+// one, or one assigned before
+func (g *Generator) assignee() int {
+	if g.vars > 0 && g.rand.Intn(3) == 0 {
+		return g.rand.Intn(g.vars)
+	}
+	g.vars++
+	return g.vars - 1
+}
+
+// expression returns an expression
+func (g *Generator) expression() string {
+	g.depth++
+	defer func() { g.depth-- }()
+
+	n := g.rand.Intn(10)
+	if g.depth > maxDepth {
+		n = 0
+	}
+	switch {
+	case n < 4:
+		return g.operand()
+	case n < 7:
+		return fmt.Sprintf("(%s %s %s)", g.expression(), pick(g.rand, binaryOperators), g.expression())
+	case n < 9:
+		// The operand is parenthesized, so - -1 does not become --1
+		return fmt.Sprintf("%s(%s)", pick(g.rand, unaryOperators), g.expression())
+	default:
+		return fmt.Sprintf("(%s ? %s : %s)", g.expression(), g.expression(), g.expression())
+	}
+}
+
+// operand returns a literal or a variable assigned before
+func (g *Generator) operand() string {
+	if g.vars > 0 && g.rand.Intn(3) == 0 {
+		return fmt.Sprintf("$v%d", g.rand.Intn(g.vars))
+	}
+	switch g.rand.Intn(4) {
+	case 0:
+		return pick(g.rand, intLiterals)
+	case 1:
+		return pick(g.rand, floatLiterals)
+	case 2:
+		return pick(g.rand, stringLiterals)
+	}
+	return pick(g.rand, otherLiterals)
+}
+
+// pick returns a random element of a list
+func pick(r *rand.Rand, list []string) string {
+	return list[r.Intn(len(list))]
+}
@@ -0,0 +1,103 @@
+package fuzz
+
+import (
+	"math/rand"
+
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+// ============================================================================
+// Mutation
+// ============================================================================
+
+// span is a token of a program that can be mutated: its text in the
+// source is its literal
+type span struct {
+	start, end int
+	typ        lexer.TokenType
+}
+
+// Mutate returns a variant of a program: an operator replaced by another,
+// a literal by an interesting value, or a token deleted or duplicated.
+// Programs without tokens to mutate are returned unchanged.
+func Mutate(program string, r *rand.Rand) string {
+	spans := mutableSpans(program)
+	if len(spans) == 0 {
+		return program
+	}
+	s := spans[r.Intn(len(spans))]
+	text := program[s.start:s.end]
+
+	switch r.Intn(5) {
+	case 0:
+		text = ""
+	case 1:
+		text += " " + text
+	default:
+		switch {
+		case s.typ == lexer.INTEGER:
+			text = pick(r, intLiterals)
+		case s.typ == lexer.FLOAT:
+			text = pick(r, floatLiterals)
+		case s.typ == lexer.STRING:
+			text = pick(r, stringLiterals)
+		case isOperator(text):
+			text = pick(r, binaryOperators)
+		}
+	}
+	return program[:s.start] + text + program[s.end:]
+}
+
+// mutableSpans lexes a program and returns the tokens whose source text is
+// known: those whose literal appears at their offset, and quoted strings
+func mutableSpans(program string) []span {
+	var spans []span
+	l := lexer.New(program, "mutate.php")
+	for {
+		tok := l.NextToken()
+		if tok.Type == lexer.EOF || tok.Type == lexer.ILLEGAL {
+			return spans
+		}
+		start, end := tok.Pos.Offset, tok.Pos.Offset+len(tok.Literal)
+		switch {
+		case tok.Type == lexer.OPEN_TAG || tok.Type == lexer.COMMENT || tok.Type == lexer.DOC_COMMENT:
+			continue
+		case tok.Type == lexer.STRING:
+			// The literal is the decoded content; the source is quoted
+			if end = quotedEnd(program, start); end < 0 {
+				continue
+			}
+		case start < 0 || end > len(program) || program[start:end] != tok.Literal:
+			continue
+		}
+		spans = append(spans, span{start: start, end: end, typ: tok.Type})
+	}
+}
+
+// quotedEnd returns the end of a single- or double-quoted string starting
+// at an offset, or -1
+func quotedEnd(program string, start int) int {
+	if start < 0 || start >= len(program) || (program[start] != '"' && program[start] != '\'') {
+		return -1
+	}
+	quote := program[start]
+	for i := start + 1; i < len(program); i++ {
+		switch program[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// isOperator reports whether a token is a binary operator generation uses
+func isOperator(text string) bool {
+	for _, op := range binaryOperators {
+		if op == text {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package fuzz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/compiler"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ============================================================================
+// Running Programs
+// ============================================================================
+
+// Outcome is the result of running a program. Errors are not displayed,
+// so the output is the program's own, and an uncaught error shows in the
+// exit status.
+type Outcome struct {
+	Output     string
+	ExitStatus int
+	Panic      string // php-go panicked: the panic and its stack
+	TimedOut   bool
+}
+
+// String describes an outcome for reports
+func (o Outcome) String() string {
+	switch {
+	case o.Panic != "":
+		return "panic: " + o.Panic
+	case o.TimedOut:
+		return "timed out"
+	}
+	return fmt.Sprintf("exit status %d, output %q", o.ExitStatus, o.Output)
+}
+
+// fatalStatus is the exit status of a script ending on a fatal error or a
+// parse error
+const fatalStatus = 255
+
+// RunPHPGo runs a program under php-go. A program still running when the
+// context ends times out; one the compiler does not return from is
+// abandoned.
+func RunPHPGo(ctx context.Context, program string, level compiler.OptimizationLevel) Outcome {
+	done := make(chan Outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- Outcome{Panic: fmt.Sprintf("%v\n%s", r, debug.Stack())}
+			}
+		}()
+		done <- runPHPGo(ctx, program, level)
+	}()
+
+	select {
+	case outcome := <-done:
+		return outcome
+	case <-ctx.Done():
+		return Outcome{TimedOut: true}
+	}
+}
+
+// runPHPGo compiles and runs a program in a new VM
+func runPHPGo(ctx context.Context, program string, level compiler.OptimizationLevel) Outcome {
+	compile := compiler.ScriptCompiler(level)
+	script, err := compile("fuzz.php", []byte(program))
+	if err != nil {
+		return Outcome{ExitStatus: fatalStatus}
+	}
+
+	machine := vm.New()
+	machine.SetContext(ctx)
+	machine.SetScriptCompiler(compile)
+	machine.SetDisplayErrors(false)
+	machine.ExecuteScript(script)
+	if ctx.Err() != nil {
+		return Outcome{TimedOut: true}
+	}
+	return Outcome{Output: machine.GetOutput(), ExitStatus: machine.ExitStatus()}
+}
+
+// Reference is the reference PHP interpreter
+type Reference struct {
+	Path string // The php binary
+}
+
+// FindReference finds the php binary: the one the PHP_BINARY environment
+// variable names, or php on the PATH
+func FindReference() (*Reference, bool) {
+	if path := os.Getenv("PHP_BINARY"); path != "" {
+		return &Reference{Path: path}, true
+	}
+	path, err := exec.LookPath("php")
+	if err != nil {
+		return nil, false
+	}
+	return &Reference{Path: path}, true
+}
+
+// Run runs a program under PHP, without a configuration file and with
+// errors hidden as in RunPHPGo
+func (r *Reference) Run(ctx context.Context, program string) (Outcome, error) {
+	dir, err := os.MkdirTemp("", "php-go-fuzz")
+	if err != nil {
+		return Outcome{}, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fuzz.php")
+	if err := os.WriteFile(path, []byte(program), 0644); err != nil {
+		return Outcome{}, err
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.Path, "-n", "-d", "display_errors=0", "-d", "log_errors=0", path)
+	cmd.Stdout = &stdout
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		return Outcome{TimedOut: true}, nil
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return Outcome{Output: stdout.String()}, nil
+	case errors.As(err, &exitErr):
+		return Outcome{Output: stdout.String(), ExitStatus: exitErr.ExitCode()}, nil
+	}
+	return Outcome{}, fmt.Errorf("running %s: %w", r.Path, err)
+}
+
+// Version returns the version PHP reports
+func (r *Reference) Version(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, r.Path, "-n", "-r", "echo PHP_VERSION;").Output()
+	if err != nil {
+		return "", fmt.Errorf("running %s: %w", r.Path, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
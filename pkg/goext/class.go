@@ -0,0 +1,173 @@
+package goext
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/embed"
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ============================================================================
+// Classes
+// ============================================================================
+
+// Class is a class of an extension. Its parent and interfaces may be
+// classes of the engine, of extensions registered before, or earlier
+// classes of the same extension. Objects can keep Go state in the
+// Internal field of their types.Object, set by the constructor.
+type Class struct {
+	Name       string
+	Parent     string
+	Interfaces []string
+	Abstract   bool
+	Final      bool
+	Constants  []*Constant
+	Properties []*Property
+	Methods    []*Method
+}
+
+// Property is a property of a Class
+type Property struct {
+	Name       string
+	Type       string
+	Default    interface{} // Converted with embed.ToValue
+	Static     bool
+	ReadOnly   bool
+	Visibility types.PropertyVisibility
+}
+
+// Method is a method of a Class. Like the handler of a Function, its
+// handler receives the arguments checked against Args; $this is nil for
+// static methods.
+type Method struct {
+	Name       string
+	Args       []Arg
+	Returns    string
+	Static     bool
+	Final      bool
+	Abstract   bool // Declared without a handler, for subclasses to implement
+	Visibility types.PropertyVisibility
+	Handler    vm.NativeMethod
+}
+
+// decl checks a class and converts it into the declaration VMs link
+func (c *Class) decl() (*vm.ClassDecl, error) {
+	if !identifier.MatchString(c.Name) {
+		return nil, fmt.Errorf("invalid class name %q", c.Name)
+	}
+	class := types.NewClassEntry(strings.TrimPrefix(c.Name, "\\"))
+	class.IsAbstract = c.Abstract
+	class.IsFinal = c.Final
+
+	for _, constant := range c.Constants {
+		if !identifier.MatchString(constant.Name) || strings.Contains(constant.Name, "\\") {
+			return nil, fmt.Errorf("invalid constant name %s::%s", class.Name, constant.Name)
+		}
+		value, err := embed.ToValue(constant.Value)
+		if err != nil {
+			return nil, fmt.Errorf("constant %s::%s: %w", class.Name, constant.Name, err)
+		}
+		class.Constants[constant.Name] = &types.ClassConstant{Name: constant.Name, Value: value, Visibility: types.VisibilityPublic}
+	}
+
+	for _, prop := range c.Properties {
+		if err := addProperty(class, prop); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, method := range c.Methods {
+		if err := addMethod(class, method); err != nil {
+			return nil, err
+		}
+	}
+
+	return &vm.ClassDecl{Class: class, Parent: c.Parent, Interfaces: c.Interfaces}, nil
+}
+
+// addProperty declares a property of a class
+func addProperty(class *types.ClassEntry, prop *Property) error {
+	if !identifier.MatchString(prop.Name) || strings.Contains(prop.Name, "\\") {
+		return fmt.Errorf("invalid property name %s::$%s", class.Name, prop.Name)
+	}
+	if _, exists := class.Properties[prop.Name]; exists {
+		return fmt.Errorf("cannot redeclare %s::$%s", class.Name, prop.Name)
+	}
+	def := &types.PropertyDef{
+		Name:           prop.Name,
+		Visibility:     prop.Visibility,
+		IsStatic:       prop.Static,
+		Type:           prop.Type,
+		IsReadOnly:     prop.ReadOnly,
+		DeclaringClass: class.Name,
+	}
+	// Untyped properties default to null, typed ones are uninitialized
+	if prop.Default != nil || prop.Type == "" {
+		value, err := embed.ToValue(prop.Default)
+		if err != nil {
+			return fmt.Errorf("property %s::$%s: %w", class.Name, prop.Name, err)
+		}
+		def.HasDefault = prop.Default != nil
+		def.Default = value
+	}
+	class.Properties[prop.Name] = def
+	class.PropertyOrder = append(class.PropertyOrder, prop.Name)
+
+	if prop.Static {
+		class.StaticProperties[prop.Name] = types.NewNull()
+		if def.Default != nil {
+			class.StaticProperties[prop.Name] = def.Default
+		}
+	} else if def.Default != nil {
+		class.DefaultProperties[prop.Name] = def.Default
+	}
+	return nil
+}
+
+// addMethod declares a method of a class
+func addMethod(class *types.ClassEntry, method *Method) error {
+	if !identifier.MatchString(method.Name) || strings.Contains(method.Name, "\\") {
+		return fmt.Errorf("invalid method name %s::%s", class.Name, method.Name)
+	}
+	name := class.Name + "::" + method.Name
+	for existing := range class.Methods {
+		if strings.EqualFold(existing, method.Name) {
+			return fmt.Errorf("cannot redeclare %s()", name)
+		}
+	}
+	switch {
+	case method.Abstract && method.Handler != nil:
+		return fmt.Errorf("abstract method %s() cannot have a handler", name)
+	case !method.Abstract && method.Handler == nil:
+		return fmt.Errorf("method %s() has no handler", name)
+	case method.Abstract && !class.IsAbstract:
+		return fmt.Errorf("class %s declares abstract method %s() and must be abstract", class.Name, method.Name)
+	}
+	params, err := parameters(name, method.Args)
+	if err != nil {
+		return err
+	}
+
+	lower := strings.ToLower(method.Name)
+	def := &types.MethodDef{
+		Name:           method.Name,
+		Visibility:     method.Visibility,
+		IsStatic:       method.Static,
+		IsFinal:        method.Final,
+		IsAbstract:     method.Abstract,
+		NumParams:      len(params),
+		Parameters:     params,
+		ReturnType:     method.Returns,
+		IsConstructor:  lower == "__construct",
+		IsDestructor:   lower == "__destruct",
+		IsMagic:        strings.HasPrefix(method.Name, "__"),
+		DeclaringClass: class.Name,
+	}
+	if method.Handler != nil {
+		def.Handler = method.Handler
+	}
+	class.Methods[method.Name] = def
+	return nil
+}
@@ -0,0 +1,69 @@
+package goext
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/embed"
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// ============================================================================
+// Functions and Arguments
+// ============================================================================
+
+// Function is a global function of an extension. The handler receives
+// the arguments checked against Args: coerced to their types, with the
+// omitted optional ones set to their defaults.
+type Function struct {
+	Name    string
+	Args    []Arg
+	Returns string // Return type declaration, reported by reflection
+	Handler vm.BuiltinFunction
+}
+
+// Arg declares a parameter of a function or method
+type Arg struct {
+	Name     string
+	Type     string      // Type declaration such as "int", "?string" or "array|string"; "" accepts any value
+	Optional bool        // The argument may be omitted
+	Default  interface{} // Value of an omitted optional argument, converted with embed.ToValue
+	Variadic bool        // Collects the remaining arguments; only the last parameter
+	ByRef    bool        // Passed by reference; the handler assigns through the reference
+}
+
+// parameters converts the argument declarations of a function
+func parameters(function string, args []Arg) ([]*types.ParameterDef, error) {
+	params := make([]*types.ParameterDef, len(args))
+	optional := false
+	for i, arg := range args {
+		if !identifier.MatchString(arg.Name) {
+			return nil, fmt.Errorf("%s(): invalid parameter name %q", function, arg.Name)
+		}
+		switch {
+		case arg.Variadic && i != len(args)-1:
+			return nil, fmt.Errorf("%s(): only the last parameter can be variadic", function)
+		case arg.Optional:
+			optional = true
+		case optional && !arg.Variadic:
+			return nil, fmt.Errorf("%s(): required parameter $%s follows an optional one", function, arg.Name)
+		}
+
+		param := &types.ParameterDef{
+			Name:        arg.Name,
+			Type:        arg.Type,
+			HasDefault:  arg.Optional,
+			IsVariadic:  arg.Variadic,
+			PassedByRef: arg.ByRef,
+		}
+		if arg.Optional {
+			value, err := embed.ToValue(arg.Default)
+			if err != nil {
+				return nil, fmt.Errorf("%s(): default of $%s: %w", function, arg.Name, err)
+			}
+			param.Default = value
+		}
+		params[i] = param
+	}
+	return params, nil
+}
@@ -1,10 +1,205 @@
-package goext
-
-// Package goext implements Go integration features.
+// Package goext lets Go code extend PHP as Zend extensions do: an
+// Extension declares functions, classes, constants and ini directives,
+// and once registered they exist in every VM created afterwards.
 //
-// This package will be implemented in Phase 8.
-// See docs/phases/08-go-integration/ for implementation details.
+//	func init() {
+//		goext.MustRegister(&goext.Extension{
+//			Name:    "greeter",
+//			Version: "1.0.0",
+//			Functions: []*goext.Function{{
+//				Name:    "greet",
+//				Args:    []goext.Arg{{Name: "name", Type: "string"}},
+//				Returns: "string",
+//				Handler: func(vm *vm.VM, args []*types.Value) (*types.Value, error) {
+//					return types.NewString("Hello, " + args[0].ToString()), nil
+//				},
+//			}},
+//		})
+//	}
 //
-// This includes FFI, type marshaling, and native extension API.
+// The arguments of functions and methods are declared with Arg: calls are
+// checked and their arguments coerced to the declared types before the
+// handler runs, as PHP does for internal functions, so handlers read
+// their arguments without checking them. Reflection reports the
+// declarations, and extension_loaded() and phpversion() the extension.
+package goext
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/embed"
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// Extension is a PHP extension written in Go
+type Extension struct {
+	Name      string
+	Version   string
+	Functions []*Function
+	Classes   []*Class // Declared in order, so a class can extend an earlier one
+	Constants []*Constant
+	Ini       []*Directive
+
+	// Startup, if set, runs when a VM has loaded the members of the
+	// extension, like the MINIT function of a Zend extension
+	Startup func(vm *vm.VM) error
+}
+
+// Constant is a global constant, or a class constant of a Class
+type Constant struct {
+	Name  string
+	Value interface{} // Converted with embed.ToValue
+}
+
+// Directive is an ini directive of an extension, settable in the
+// configuration file and, if Access allows it, by ini_set()
+type Directive struct {
+	Name     string
+	Default  string
+	Access   int // runtime.INI_* flags; 0 means runtime.INI_ALL
+	OnChange func(vm *vm.VM, value string) error
+}
+
+// identifier matches the name of a function, class or constant, which
+// may be namespaced
+var identifier = regexp.MustCompile(`^\\?[A-Za-z_\x80-\xff][A-Za-z0-9_\x80-\xff]*(\\[A-Za-z_\x80-\xff][A-Za-z0-9_\x80-\xff]*)*$`)
+
+// Register checks an extension and registers it for the VMs created
+// afterwards. It fails if a declaration is invalid or clashes with an
+// existing function, class or constant.
+func Register(ext *Extension) error {
+	loader, err := newLoader(ext)
+	if err != nil {
+		return fmt.Errorf("extension %s: %w", ext.Name, err)
+	}
+	return vm.RegisterExtension(&vm.Extension{Name: ext.Name, Version: ext.Version, Load: loader.load})
+}
+
+// MustRegister registers an extension like Register, panicking if it
+// fails; it suits extensions registered by init functions
+func MustRegister(ext *Extension) {
+	if err := Register(ext); err != nil {
+		panic("goext: " + err.Error())
+	}
+}
+
+// ============================================================================
+// Loading
+// ============================================================================
+
+// loader declares the members of an extension in VMs. The declarations
+// are checked and converted once, when the extension is registered.
+type loader struct {
+	ext       *Extension
+	functions []loadedFunction
+	classes   []*vm.ClassDecl
+	constants map[string]*types.Value
+}
+
+// loadedFunction is a function ready to be declared
+type loadedFunction struct {
+	name       string
+	handler    vm.BuiltinFunction
+	params     []*types.ParameterDef
+	returnType string
+}
+
+// newLoader checks the declarations of an extension and converts them
+func newLoader(ext *Extension) (*loader, error) {
+	l := &loader{ext: ext, constants: make(map[string]*types.Value)}
+
+	for _, fn := range ext.Functions {
+		if !identifier.MatchString(fn.Name) {
+			return nil, fmt.Errorf("invalid function name %q", fn.Name)
+		}
+		if fn.Handler == nil {
+			return nil, fmt.Errorf("function %s() has no handler", fn.Name)
+		}
+		params, err := parameters(fn.Name, fn.Args)
+		if err != nil {
+			return nil, err
+		}
+		l.functions = append(l.functions, loadedFunction{
+			name:       strings.TrimPrefix(fn.Name, "\\"),
+			handler:    fn.Handler,
+			params:     params,
+			returnType: fn.Returns,
+		})
+	}
+
+	for _, class := range ext.Classes {
+		decl, err := class.decl()
+		if err != nil {
+			return nil, err
+		}
+		l.classes = append(l.classes, decl)
+	}
+
+	for _, constant := range ext.Constants {
+		if !identifier.MatchString(constant.Name) {
+			return nil, fmt.Errorf("invalid constant name %q", constant.Name)
+		}
+		value, err := embed.ToValue(constant.Value)
+		if err != nil {
+			return nil, fmt.Errorf("constant %s: %w", constant.Name, err)
+		}
+		l.constants[strings.TrimPrefix(constant.Name, "\\")] = value
+	}
+
+	for _, directive := range ext.Ini {
+		if directive.Name == "" || strings.ContainsAny(directive.Name, " =;") {
+			return nil, fmt.Errorf("invalid ini directive name %q", directive.Name)
+		}
+	}
+	return l, nil
+}
+
+// load declares the members of the extension in a VM: its ini directives
+// first, so its startup can read them, then its constants, functions and
+// classes
+func (l *loader) load(machine *vm.VM) error {
+	config := machine.Config()
+	for _, directive := range l.ext.Ini {
+		if _, exists := config.Get(directive.Name); exists {
+			return fmt.Errorf("ini directive %s is already registered", directive.Name)
+		}
+		access := directive.Access
+		if access == 0 {
+			access = runtime.INI_ALL
+		}
+		config.Register(runtime.IniDirective{Name: directive.Name, Default: directive.Default, Access: access})
+		if onChange := directive.OnChange; onChange != nil {
+			config.OnChange(directive.Name, func(value string) error {
+				return onChange(machine, value)
+			})
+		}
+	}
+
+	for name, value := range l.constants {
+		if !machine.DefineConstant(name, value.Copy()) {
+			return fmt.Errorf("constant %s is already defined", name)
+		}
+	}
+
+	for _, fn := range l.functions {
+		if _, exists := machine.GetBuiltin(fn.name); exists {
+			return fmt.Errorf("function %s() already exists", fn.name)
+		}
+		machine.DeclareBuiltin(fn.name, fn.handler, fn.params, fn.returnType)
+	}
+
+	for _, decl := range l.classes {
+		if err := machine.LinkClass(decl); err != nil {
+			return err
+		}
+	}
 
-// TODO: Implement in Phase 8
+	if l.ext.Startup != nil {
+		return l.ext.Startup(machine)
+	}
+	return nil
+}
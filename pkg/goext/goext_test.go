@@ -0,0 +1,227 @@
+package goext
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// greeting records the values the test extension's ini directive was set to
+var greeting []string
+
+func init() {
+	MustRegister(&Extension{
+		Name:    "goext_test",
+		Version: "1.2.3",
+		Functions: []*Function{{
+			Name: "goext_repeat",
+			Args: []Arg{
+				{Name: "text", Type: "string"},
+				{Name: "times", Type: "int", Optional: true, Default: 2},
+			},
+			Returns: "string",
+			Handler: func(vm *vm.VM, args []*types.Value) (*types.Value, error) {
+				return types.NewString(strings.Repeat(args[0].ToString(), int(args[1].ToInt()))), nil
+			},
+		}},
+		Constants: []*Constant{{Name: "GOEXT_TEST_ANSWER", Value: 42}},
+		Ini: []*Directive{{
+			Name:    "goext_test.greeting",
+			Default: "hello",
+			OnChange: func(vm *vm.VM, value string) error {
+				greeting = append(greeting, value)
+				return nil
+			},
+		}},
+		Classes: []*Class{
+			{
+				Name:      "GoextCounter",
+				Constants: []*Constant{{Name: "STEP", Value: 1}},
+				Methods: []*Method{
+					{
+						Name: "__construct",
+						Args: []Arg{{Name: "start", Type: "int", Optional: true, Default: 0}},
+						Handler: func(vm *vm.VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+							count := args[0].ToInt()
+							this.Internal = &count
+							return types.NewNull(), nil
+						},
+					},
+					{
+						Name:    "increment",
+						Args:    []Arg{{Name: "by", Type: "int", Optional: true, Default: 1}},
+						Returns: "int",
+						Handler: func(vm *vm.VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+							count := this.Internal.(*int64)
+							*count += args[0].ToInt()
+							return types.NewInt(*count), nil
+						},
+					},
+				},
+			},
+			{Name: "GoextTenCounter", Parent: "GoextCounter", Interfaces: []string{"JsonSerializable"}, Methods: []*Method{{
+				Name: "jsonSerialize",
+				Handler: func(vm *vm.VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+					return types.NewInt(10), nil
+				},
+			}}},
+		},
+	})
+}
+
+// extensionCaller returns a function calling PHP callables in a new VM,
+// which has the test extension loaded
+func extensionCaller(t *testing.T) (*vm.VM, func(callable *types.Value, args ...*types.Value) (*types.Value, error)) {
+	t.Helper()
+	machine := vm.New()
+	return machine, func(callable *types.Value, args ...*types.Value) (*types.Value, error) {
+		return machine.CallCallable(callable, args)
+	}
+}
+
+// method returns the callable [$object, $name]
+func method(object *types.Value, name string) *types.Value {
+	callable := types.NewEmptyArray()
+	callable.Append(object)
+	callable.Append(types.NewString(name))
+	return types.NewArray(callable)
+}
+
+func TestFunction(t *testing.T) {
+	_, call := extensionCaller(t)
+	repeat := types.NewString("goext_repeat")
+
+	if result, err := call(repeat, types.NewString("ab")); err != nil || result.ToString() != "abab" {
+		t.Errorf("goext_repeat(\"ab\") = %v, %v", result, err)
+	}
+	// Coercive typing mode converts the numeric string
+	if result, err := call(repeat, types.NewString("ab"), types.NewString("3")); err != nil || result.ToString() != "ababab" {
+		t.Errorf("goext_repeat(\"ab\", \"3\") = %v, %v", result, err)
+	}
+
+	errorTests := []struct {
+		args []*types.Value
+		want string
+	}{
+		{nil, "ArgumentCountError: goext_repeat() expects at least 1 argument, 0 given"},
+		{[]*types.Value{types.NewString("a"), types.NewInt(1), types.NewInt(2)}, "goext_repeat() expects at most 2 arguments, 3 given"},
+		{[]*types.Value{types.NewString("a"), types.NewString("x")}, "TypeError: goext_repeat(): Argument #2 ($times) must be of type int, string given"},
+	}
+	for _, tt := range errorTests {
+		if _, err := call(repeat, tt.args...); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("goext_repeat(%v) error = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestConstantsAndIni(t *testing.T) {
+	machine, call := extensionCaller(t)
+
+	if value, ok := machine.LookupConstant("GOEXT_TEST_ANSWER"); !ok || value.ToInt() != 42 {
+		t.Errorf("GOEXT_TEST_ANSWER = %v", value)
+	}
+	if value, err := call(types.NewString("ini_get"), types.NewString("goext_test.greeting")); err != nil || value.ToString() != "hello" {
+		t.Errorf("ini_get() = %v, %v", value, err)
+	}
+
+	greeting = nil
+	if _, err := call(types.NewString("ini_set"), types.NewString("goext_test.greeting"), types.NewString("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if len(greeting) != 1 || greeting[0] != "hi" {
+		t.Errorf("OnChange received %q", greeting)
+	}
+}
+
+func TestExtensionInfo(t *testing.T) {
+	_, call := extensionCaller(t)
+
+	if loaded, _ := call(types.NewString("extension_loaded"), types.NewString("GOEXT_TEST")); !loaded.ToBool() {
+		t.Error("extension_loaded() = false")
+	}
+	if version, _ := call(types.NewString("phpversion"), types.NewString("goext_test")); version.ToString() != "1.2.3" {
+		t.Errorf("phpversion() = %v", version)
+	}
+	if version, _ := call(types.NewString("phpversion"), types.NewString("no_such_extension")); !version.IsBool() {
+		t.Errorf("phpversion() of an unknown extension = %v", version)
+	}
+}
+
+func TestClass(t *testing.T) {
+	machine, call := extensionCaller(t)
+
+	counter, err := machine.NewObject("GoextCounter", types.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := call(method(counter, "increment")); err != nil || result.ToInt() != 6 {
+		t.Errorf("increment() = %v, %v", result, err)
+	}
+	if result, err := call(method(counter, "increment"), types.NewFloat(4)); err != nil || result.ToInt() != 10 {
+		t.Errorf("increment(4.0) = %v, %v", result, err)
+	}
+	if _, err := call(method(counter, "increment"), types.NewNull()); err == nil || !strings.Contains(err.Error(), "must be of type int, null given") {
+		t.Errorf("increment(null) error = %v", err)
+	}
+
+	// The subclass inherits the constructor and implements JsonSerializable
+	ten, err := machine.NewObject("GoextTenCounter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := call(method(ten, "increment")); err != nil || result.ToInt() != 1 {
+		t.Errorf("increment() = %v, %v", result, err)
+	}
+	if json, err := call(types.NewString("json_encode"), ten); err != nil || json.ToString() != "10" {
+		t.Errorf("json_encode() = %v, %v", json, err)
+	}
+}
+
+func TestReflection(t *testing.T) {
+	machine, call := extensionCaller(t)
+
+	function, err := machine.NewObject("ReflectionFunction", types.NewString("goext_repeat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := call(method(function, "getNumberOfRequiredParameters")); err != nil || n.ToInt() != 1 {
+		t.Errorf("getNumberOfRequiredParameters() = %v, %v", n, err)
+	}
+	if n, err := call(method(function, "getNumberOfParameters")); err != nil || n.ToInt() != 2 {
+		t.Errorf("getNumberOfParameters() = %v, %v", n, err)
+	}
+	if internal, err := call(method(function, "isInternal")); err != nil || !internal.ToBool() {
+		t.Errorf("isInternal() = %v, %v", internal, err)
+	}
+}
+
+func TestRegister_Invalid(t *testing.T) {
+	handler := func(vm *vm.VM, args []*types.Value) (*types.Value, error) {
+		return types.NewNull(), nil
+	}
+	tests := []struct {
+		ext  *Extension
+		want string
+	}{
+		{&Extension{Name: "goext_test"}, "extension goext_test is already registered"},
+		{&Extension{Name: "goext_bad", Functions: []*Function{{Name: "bad name", Handler: handler}}}, `invalid function name "bad name"`},
+		{&Extension{Name: "goext_bad", Functions: []*Function{{Name: "goext_bad"}}}, "function goext_bad() has no handler"},
+		{&Extension{Name: "goext_bad", Functions: []*Function{{Name: "goext_bad", Handler: handler, Args: []Arg{
+			{Name: "a", Optional: true}, {Name: "b"},
+		}}}}, "goext_bad(): required parameter $b follows an optional one"},
+		{&Extension{Name: "goext_bad", Functions: []*Function{{Name: "json_encode", Handler: handler}}}, "function json_encode() already exists"},
+		{&Extension{Name: "goext_bad", Classes: []*Class{{Name: "GoextBad", Parent: "NoSuchClass"}}}, `Class "NoSuchClass" not found`},
+		{&Extension{Name: "goext_bad", Classes: []*Class{{Name: "GoextBad", Methods: []*Method{{Name: "run", Abstract: true}}}}},
+			"class GoextBad declares abstract method run() and must be abstract"},
+	}
+	for _, tt := range tests {
+		if err := Register(tt.ext); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Register(%s) error = %v, want %q", tt.ext.Name, err, tt.want)
+		}
+	}
+	if _, ok := vm.New().GetBuiltin("goext_bad"); ok {
+		t.Error("a failed extension was loaded")
+	}
+}
@@ -15,6 +15,9 @@ type Lexer struct {
 	line      int    // Current line number (1-based)
 	column    int    // Current column number (1-based)
 	lineStart int    // Byte offset of the start of the current line
+	base      int    // Offset of the input in its file, for embedded input
+	trivia    bool   // Whether tokens carry the trivia before them
+	html      bool   // Whether the lexer is outside of PHP tags, in trivia mode
 }
 
 // New creates a new Lexer for the given input
@@ -29,6 +32,16 @@ func New(input, filename string) *Lexer {
 	return l
 }
 
+// NewAt creates a new Lexer for input that starts at the given position
+// of a file, such as an expression embedded in a string
+func NewAt(input string, pos Position) *Lexer {
+	l := New(input, pos.Filename)
+	l.line = pos.Line
+	l.column = pos.Column
+	l.base = pos.Offset
+	return l
+}
+
 // readChar reads the next character and advances position
 func (l *Lexer) readChar() {
 	if l.readPos >= len(l.input) {
@@ -41,6 +54,12 @@ func (l *Lexer) readChar() {
 	l.column++
 }
 
+// eof reports whether the lexer is past the end of the input. l.ch is 0
+// there, but NUL bytes may also appear in strings, so it cannot tell.
+func (l *Lexer) eof() bool {
+	return l.pos >= len(l.input)
+}
+
 // peekChar returns the next character without advancing
 func (l *Lexer) peekChar() byte {
 	if l.readPos >= len(l.input) {
@@ -62,22 +81,51 @@ func (l *Lexer) peekCharN(n int) byte {
 func (l *Lexer) currentPosition() Position {
 	return Position{
 		Filename: l.filename,
-		Offset:   l.pos,
+		Offset:   l.base + l.pos,
 		Line:     l.line,
 		Column:   l.column,
 	}
 }
 
+// Input returns the source the lexer scans
+func (l *Lexer) Input() string {
+	return l.input
+}
+
 // NextToken returns the next token from the input
 func (l *Lexer) NextToken() Token {
+	if l.trivia {
+		return l.nextWithTrivia()
+	}
+	return l.nextToken()
+}
+
+// nextToken scans the next token and sets its end
+func (l *Lexer) nextToken() Token {
+	tok := l.scanToken()
+	tok.End = l.currentPosition()
+	if tok.Type == EOF {
+		tok.End = tok.Pos // The lexer reads past the end of the input
+	}
+	return tok
+}
+
+// scanToken scans the next token, leaving the lexer just past it
+func (l *Lexer) scanToken() Token {
 	var tok Token
 
 	l.skipWhitespace()
 
-	tok.Pos = l.currentPosition()
+	start := l.currentPosition()
+	tok.Pos = start
 
 	switch l.ch {
 	case 0:
+		if !l.eof() {
+			// A NUL byte in code; in strings and inline HTML it is data
+			tok = l.makeToken(ILLEGAL, string(l.ch))
+			break
+		}
 		tok.Type = EOF
 		tok.Literal = ""
 
@@ -218,7 +266,7 @@ func (l *Lexer) NextToken() Token {
 			if l.peekChar() == '<' {
 				// Could be heredoc/nowdoc (<<<)
 				l.readChar() // Consume second <, now on third <
-				tok = l.scanHeredocOrShift()
+				tok = l.scanHeredocOrShift(tok.Pos)
 				return tok
 			} else if l.peekChar() == '=' {
 				l.readChar()
@@ -379,6 +427,9 @@ func (l *Lexer) NextToken() Token {
 		}
 	}
 
+	// Operators are made at their last character: they start where the
+	// scan did
+	tok.Pos = start
 	l.readChar()
 	return tok
 }
@@ -433,6 +484,15 @@ func (l *Lexer) scanVariable() Token {
 
 	l.readChar() // consume '$'
 
+	// $$name and ${expr} are variable variables
+	if l.ch == '$' || l.ch == '{' {
+		return Token{
+			Type:    DOLLAR,
+			Literal: "$",
+			Pos:     pos,
+		}
+	}
+
 	if !isLetter(l.ch) && l.ch != '_' {
 		return Token{
 			Type:    ILLEGAL,
@@ -528,7 +588,7 @@ func (l *Lexer) scanSingleQuotedString() Token {
 
 	l.readChar() // consume opening '
 
-	for l.ch != '\'' && l.ch != 0 {
+	for l.ch != '\'' && !l.eof() {
 		if l.ch == '\\' && (l.peekChar() == '\'' || l.peekChar() == '\\') {
 			l.readChar()
 			result.WriteByte(l.ch)
@@ -543,7 +603,7 @@ func (l *Lexer) scanSingleQuotedString() Token {
 		}
 	}
 
-	if l.ch == 0 {
+	if l.eof() {
 		return Token{
 			Type:    ILLEGAL,
 			Literal: "unterminated string",
@@ -568,7 +628,7 @@ func (l *Lexer) scanDoubleQuotedString() Token {
 
 	l.readChar() // consume opening "
 
-	for l.ch != '"' && l.ch != 0 {
+	for l.ch != '"' && !l.eof() {
 		if l.ch == '\\' {
 			l.readChar()
 			switch l.ch {
@@ -604,7 +664,7 @@ func (l *Lexer) scanDoubleQuotedString() Token {
 		}
 	}
 
-	if l.ch == 0 {
+	if l.eof() {
 		return Token{
 			Type:    ILLEGAL,
 			Literal: "unterminated string",
@@ -629,7 +689,7 @@ func (l *Lexer) scanBacktickString() Token {
 
 	l.readChar() // consume opening `
 
-	for l.ch != '`' && l.ch != 0 {
+	for l.ch != '`' && !l.eof() {
 		if l.ch == '\n' {
 			l.line++
 			l.column = 0
@@ -638,7 +698,7 @@ func (l *Lexer) scanBacktickString() Token {
 		l.readChar()
 	}
 
-	if l.ch == 0 {
+	if l.eof() {
 		return Token{
 			Type:    ILLEGAL,
 			Literal: "unterminated shell execution string",
@@ -661,7 +721,7 @@ func (l *Lexer) scanSingleLineComment() Token {
 	pos := l.currentPosition()
 	start := l.pos
 
-	for l.ch != '\n' && l.ch != 0 {
+	for l.ch != '\n' && !l.eof() {
 		l.readChar()
 	}
 
@@ -674,10 +734,16 @@ func (l *Lexer) scanSingleLineComment() Token {
 	}
 }
 
-// scanMultiLineComment scans a multi-line comment (/* ... */)
+// scanMultiLineComment scans a multi-line comment (/* ... */). Comments
+// opening with /** and a whitespace are doc comments, which the parser
+// attaches to the following declaration.
 func (l *Lexer) scanMultiLineComment() Token {
 	pos := l.currentPosition()
 	start := l.pos
+	tokenType := COMMENT
+	if strings.HasPrefix(l.input[l.pos:], "/**") && l.pos+3 < len(l.input) && strings.IndexByte(" \t\n\r", l.input[l.pos+3]) >= 0 {
+		tokenType = DOC_COMMENT
+	}
 
 	l.readChar() // consume '/'
 	l.readChar() // consume '*'
@@ -688,7 +754,7 @@ func (l *Lexer) scanMultiLineComment() Token {
 			l.readChar()
 			break
 		}
-		if l.ch == 0 {
+		if l.eof() {
 			return Token{
 				Type:    ILLEGAL,
 				Literal: "unterminated comment",
@@ -705,7 +771,7 @@ func (l *Lexer) scanMultiLineComment() Token {
 	literal := l.input[start:l.pos]
 
 	return Token{
-		Type:    COMMENT,
+		Type:    tokenType,
 		Literal: literal,
 		Pos:     pos,
 	}
@@ -731,8 +797,9 @@ func (l *Lexer) scanPHPTag() Token {
 		l.readChar()
 		l.readChar()
 		l.readChar()
-		// Optionally consume following whitespace
-		if l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+		// Optionally consume following whitespace, which is trivia of
+		// its own in trivia mode
+		if !l.trivia && (l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r') {
 			l.skipWhitespace()
 		}
 		return Token{
@@ -750,8 +817,9 @@ func (l *Lexer) scanPHPTag() Token {
 	}
 }
 
-// scanHeredocOrShift determines if <<< is heredoc/nowdoc or left shift
-func (l *Lexer) scanHeredocOrShift() Token {
+// scanHeredocOrShift determines if <<< is heredoc/nowdoc or left shift;
+// pos is the position of the first <
+func (l *Lexer) scanHeredocOrShift(pos Position) Token {
 	// We're already on the third '<'
 	// Consume it and check for heredoc label
 	l.readChar() // Move past third '<'
@@ -763,11 +831,11 @@ func (l *Lexer) scanHeredocOrShift() Token {
 		// Not a valid heredoc, this is an error case
 		// We already consumed <<<, so we can't easily recover
 		// Return illegal token
-		return l.makeToken(ILLEGAL, "invalid heredoc/nowdoc syntax")
+		return Token{Type: ILLEGAL, Literal: "invalid heredoc/nowdoc syntax", Pos: pos}
 	}
 
 	// Valid heredoc/nowdoc found
-	return l.scanHeredoc(label, isNowdoc)
+	return l.scanHeredoc(label, isNowdoc, pos)
 }
 
 // Helper functions
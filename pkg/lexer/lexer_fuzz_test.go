@@ -0,0 +1,40 @@
+package lexer
+
+import "testing"
+
+// fuzzSeeds are the seed inputs of the lexer and parser fuzz tests
+var fuzzSeeds = []string{
+	"",
+	"<?php",
+	"<?php echo 'Hello, World!';",
+	"<?php $a = 1 + 2 * 3; echo \"$a {$a} ${a}\\n\";",
+	"<?php function f(int $x = 0, ...$rest): ?string { return $x <=> 1; }",
+	"<?php class A extends B implements C { public const X = 1; private ?int $y = null; }",
+	"<?php $s = <<<EOT\nline $x\nEOT;\n$n = <<<'N'\nraw\nN;\n",
+	"<?php /* comment */ // line\n# hash\n#[Attr] fn($x) => $x?->y ?? 0x1F + 0b101 + 0o17 + 1_000 + 1.5e3;",
+	"html <?= $x ?> more <?php if ($a): ?>x<?php endif; ?>",
+	"<?php \"unterminated",
+	"<?php /* unterminated",
+	"<?php $a = '\\'';\n`ls`; $$b; @$c; &$d;",
+}
+
+// FuzzLexer checks that the lexer reaches the end of any input without
+// panicking
+func FuzzLexer(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		l := New(input, "fuzz.php")
+		// Every token but the last consumes input, so more tokens than
+		// bytes means the lexer is stuck
+		for i := 0; ; i++ {
+			if tok := l.NextToken(); tok.Type == EOF {
+				return
+			}
+			if i > 2*len(input)+8 {
+				t.Fatalf("no EOF after %d tokens of %d bytes of input", i, len(input))
+			}
+		}
+	})
+}
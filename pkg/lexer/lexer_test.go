@@ -198,6 +198,26 @@ func TestLexerComments(t *testing.T) {
 	}
 }
 
+func TestLexerDocComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected TokenType
+	}{
+		{"/** Doc comment */", DOC_COMMENT},
+		{"/**\n * @return int\n */", DOC_COMMENT},
+		{"/***/", COMMENT},
+		{"/**/", COMMENT},
+		{"/**not a doc comment */", COMMENT},
+	}
+
+	for _, tt := range tests {
+		tok := New(tt.input, "test.php").NextToken()
+		if tok.Type != tt.expected || tok.Literal != tt.input {
+			t.Errorf("%q: expected %s, got %s %q", tt.input, tt.expected, tok.Type, tok.Literal)
+		}
+	}
+}
+
 func TestLexerVariables(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -407,3 +427,44 @@ class UserController {
 		}
 	}
 }
+
+func TestLexerTokenSpans(t *testing.T) {
+	input := "<?php\n$total += f(...$args) . \"a\nb\";"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedText    string
+	}{
+		{"<?php", "<?php\n"}, // The open tag includes its newline, as in PHP
+		{"$total", "$total"},
+		{"+=", "+="},
+		{"f", "f"},
+		{"(", "("},
+		{"...", "..."},
+		{"$args", "$args"},
+		{")", ")"},
+		{".", "."},
+		{"a\nb", "\"a\nb\""},
+		{";", ";"},
+		{"", ""},
+	}
+
+	l := New(input, "test.php")
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if text := input[tok.Pos.Offset:tok.End.Offset]; text != tt.expectedText {
+			t.Errorf("tests[%d] - span wrong. expected=%q, got=%q", i, tt.expectedText, text)
+		}
+	}
+
+	// A string spanning lines ends on its last line
+	l = New(input, "test.php")
+	for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+		if tok.Literal == "a\nb" && (tok.End.Line != 3 || tok.End.Column != 3) {
+			t.Errorf("expected the string to end at 3:3, got %s", tok.End)
+		}
+	}
+}
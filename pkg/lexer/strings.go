@@ -1,161 +1,111 @@
 package lexer
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 )
 
-// scanHeredoc scans a heredoc string (<<<EOT ... EOT)
-func (l *Lexer) scanHeredoc(label string, isNowdoc bool) Token {
-	pos := l.currentPosition()
-	var result strings.Builder
+// scanHeredoc scans the body of a heredoc or nowdoc after its <<<LABEL.
+// As in PHP 7.3+, the closing label may be indented and followed by any
+// character that cannot continue the label; its indentation is removed
+// from every line of the body, and the newline before it is not part of
+// the string. Heredoc bodies are kept raw: escapes and interpolation are
+// resolved by the parser (see SplitInterpolation).
+func (l *Lexer) scanHeredoc(label string, isNowdoc bool, pos Position) Token {
+	// The label must end its line
+	if !l.skipNewline() {
+		return Token{Type: ILLEGAL, Literal: "invalid heredoc/nowdoc syntax", Pos: pos}
+	}
 
-	// Skip the newline after <<<LABEL
-	if l.ch == '\n' {
-		l.line++
-		l.column = 0
-		l.lineStart = l.pos + 1
-		l.readChar()
-	} else if l.ch == '\r' && l.peekChar() == '\n' {
-		l.readChar() // skip \r
-		l.line++
-		l.column = 0
-		l.lineStart = l.pos + 1
-		l.readChar() // skip \n
-	}
-
-	// Scan until we find the closing label on its own line
-	for l.ch != 0 {
-		// Check if this line starts with the closing label
-		if l.checkHeredocEnd(label) {
-			break
+	var lines []string
+	for {
+		if l.eof() {
+			return Token{Type: ILLEGAL, Literal: "unterminated heredoc", Pos: pos}
 		}
-
-		// Read the entire line
-		for l.ch != '\n' && l.ch != '\r' && l.ch != 0 {
-			if !isNowdoc && l.ch == '$' {
-				// TODO: Handle interpolation in heredoc
-				// For now, just include the $ in the string
-				result.WriteByte(l.ch)
-				l.readChar()
-			} else if !isNowdoc && l.ch == '\\' && (l.peekChar() == '\\' || l.peekChar() == '$') {
-				// Escape sequences in heredoc
-				l.readChar()
-				result.WriteByte(l.ch)
-				l.readChar()
-			} else {
-				result.WriteByte(l.ch)
-				l.readChar()
+		if indent, ok := l.scanHeredocEnd(label); ok {
+			body, err := stripHeredocIndentation(lines, indent)
+			if err != nil {
+				return Token{Type: ILLEGAL, Literal: err.Error(), Pos: pos}
 			}
-		}
 
-		// Include newline in heredoc content (except for the last line)
-		if l.ch == '\n' {
-			result.WriteByte('\n')
-			l.line++
-			l.column = 0
-			l.lineStart = l.pos + 1
-			l.readChar()
-		} else if l.ch == '\r' {
-			if l.peekChar() == '\n' {
-				result.WriteByte('\n')
-				l.readChar() // skip \r
-				l.readChar() // skip \n
-			} else {
-				result.WriteByte('\n')
-				l.readChar()
+			tokenType := HEREDOC
+			if isNowdoc {
+				tokenType = NOWDOC
 			}
-			l.line++
-			l.column = 0
-			l.lineStart = l.pos + 1
+			return Token{Type: tokenType, Literal: body, Pos: pos}
 		}
-	}
 
-	if l.ch == 0 {
-		return Token{
-			Type:    ILLEGAL,
-			Literal: "unterminated heredoc",
-			Pos:     pos,
+		start := l.pos
+		for l.ch != '\n' && l.ch != '\r' && !l.eof() {
+			l.readChar()
 		}
+		lines = append(lines, l.input[start:l.pos])
+		l.skipNewline()
 	}
+}
 
-	// Consume the closing label
-	for l.ch != '\n' && l.ch != '\r' && l.ch != ';' && l.ch != 0 {
+// skipNewline consumes a \n, \r\n or \r line break, if present
+func (l *Lexer) skipNewline() bool {
+	switch {
+	case l.ch == '\r' && l.peekChar() == '\n':
 		l.readChar()
+	case l.ch != '\n' && l.ch != '\r':
+		return false
 	}
+	l.readChar()
+	l.line++
+	l.column = 1
+	l.lineStart = l.pos
+	return true
+}
 
-	// Don't consume semicolon - it's part of the statement syntax
-	// Just consume trailing newline if present
-	if l.ch == '\n' {
-		l.line++
-		l.column = 0
-		l.lineStart = l.pos + 1
-		l.readChar()
-	} else if l.ch == '\r' {
-		l.readChar()
-		if l.ch == '\n' {
-			l.readChar()
-		}
-		l.line++
-		l.column = 0
-		l.lineStart = l.pos + 1
+// scanHeredocEnd checks if the current line closes the heredoc: optional
+// spaces or tabs, the label, and no label character after it. If so it
+// consumes the indentation and the label and returns the indentation.
+func (l *Lexer) scanHeredocEnd(label string) (string, bool) {
+	rest := l.input[l.pos:]
+	i := 0
+	for i < len(rest) && (rest[i] == ' ' || rest[i] == '\t') {
+		i++
 	}
-
-	tokenType := HEREDOC
-	if isNowdoc {
-		tokenType = NOWDOC
+	if !strings.HasPrefix(rest[i:], label) {
+		return "", false
 	}
-
-	return Token{
-		Type:    tokenType,
-		Literal: result.String(),
-		Pos:     pos,
+	if end := i + len(label); end < len(rest) && isNameChar(rest[end]) {
+		return "", false
 	}
-}
-
-// checkHeredocEnd checks if the current position is at the heredoc closing label
-func (l *Lexer) checkHeredocEnd(label string) bool {
-	// Save current position
-	savedPos := l.pos
-	savedReadPos := l.readPos
-	savedCh := l.ch
-	savedLine := l.line
-	savedColumn := l.column
 
-	// Skip leading whitespace (PHP 7.3+ allows indented closing tags)
-	for l.ch == ' ' || l.ch == '\t' {
+	for n := i + len(label); n > 0; n-- {
 		l.readChar()
 	}
+	return rest[:i], true
+}
 
-	// Check if the label matches
-	matches := true
-	for i := 0; i < len(label); i++ {
-		if l.ch != label[i] {
-			matches = false
-			break
-		}
-		l.readChar()
+// stripHeredocIndentation removes the closing label's indentation from
+// each line and joins them. Lines holding only whitespace may be indented
+// less; any other line indented less is an error, as is mixing tabs and
+// spaces.
+func stripHeredocIndentation(lines []string, indent string) (string, error) {
+	if strings.Contains(indent, " ") && strings.Contains(indent, "\t") {
+		return "", errors.New("invalid indentation - tabs and spaces cannot be mixed")
 	}
 
-	// After the label, we should see ; or newline or EOF
-	if matches {
-		if l.ch == ';' || l.ch == '\n' || l.ch == '\r' || l.ch == 0 {
-			// Restore position (we'll consume it in scanHeredoc)
-			l.pos = savedPos
-			l.readPos = savedReadPos
-			l.ch = savedCh
-			l.line = savedLine
-			l.column = savedColumn
-			return true
+	for n, line := range lines {
+		skip := 0
+		for skip < len(indent) && skip < len(line) && (line[skip] == ' ' || line[skip] == '\t') {
+			if line[skip] != indent[0] {
+				return "", errors.New("invalid indentation - tabs and spaces cannot be mixed")
+			}
+			skip++
+		}
+		if skip < len(indent) && skip < len(line) {
+			return "", fmt.Errorf("invalid body indentation level (expecting an indentation level of at least %d)", len(indent))
 		}
+		lines[n] = line[skip:]
 	}
-
-	// Restore position
-	l.pos = savedPos
-	l.readPos = savedReadPos
-	l.ch = savedCh
-	l.line = savedLine
-	l.column = savedColumn
-	return false
+	return strings.Join(lines, "\n"), nil
 }
 
 // scanHeredocLabel scans the heredoc/nowdoc label after <<<
@@ -167,14 +117,14 @@ func (l *Lexer) scanHeredocLabel() (string, bool) {
 		l.readChar()
 	}
 
-	// Check for nowdoc (quoted label)
+	// <<<'LABEL' starts a nowdoc, <<<"LABEL" is the same as <<<LABEL
 	if l.ch == '\'' || l.ch == '"' {
-		isNowdoc = true
+		isNowdoc = l.ch == '\''
 		quote := l.ch
 		l.readChar()
 
 		start := l.pos
-		for l.ch != quote && l.ch != 0 && l.ch != '\n' {
+		for l.ch != quote && !l.eof() && l.ch != '\n' {
 			l.readChar()
 		}
 
@@ -204,75 +154,280 @@ func (l *Lexer) scanHeredocLabel() (string, bool) {
 
 // StringInterpolation represents a part of an interpolated string
 type StringInterpolation struct {
-	IsVariable bool   // true if this is a variable, false if it's text
-	Value      string // the variable name (with $) or the text content
+	IsVariable bool   // true if this is an embedded expression, false if it's text
+	Value      string // the expression source (with $) or the unescaped text
+	Offset     int    // byte offset of the part in the raw string
 }
 
-// parseStringInterpolation parses string interpolation in double-quoted strings
-// Returns a list of parts (text and variables)
-func parseStringInterpolation(input string) []StringInterpolation {
+// SplitInterpolation splits the raw body of a double-quoted string or
+// heredoc into text and embedded expressions. Escape sequences in text
+// parts are resolved; quote is the delimiter that may be escaped ('"' for
+// strings, 0 for heredocs). Expression parts hold PHP source: "$var",
+// "$arr['key']" and "$obj->prop" for the simple syntax, the contents of
+// "{$expr}", and "$name" or "${expr}" for "${...}".
+func SplitInterpolation(raw string, quote byte) []StringInterpolation {
 	var parts []StringInterpolation
-	var current strings.Builder
-	i := 0
-
-	for i < len(input) {
-		ch := input[i]
-
-		if ch == '$' && i+1 < len(input) {
-			// Check if this is a variable
-			next := input[i+1]
-			if isLetter(byte(next)) || next == '_' {
-				// Save any pending text
-				if current.Len() > 0 {
-					parts = append(parts, StringInterpolation{
-						IsVariable: false,
-						Value:      current.String(),
-					})
-					current.Reset()
-				}
-
-				// Extract variable name
-				varStart := i
-				i++ // skip $
-				for i < len(input) && (isLetter(input[i]) || isDigit(input[i]) || input[i] == '_') {
-					i++
-				}
+	textStart := 0
+
+	flush := func(end int) {
+		if end > textStart {
+			parts = append(parts, StringInterpolation{
+				Value:  unescapeString(raw[textStart:end], quote),
+				Offset: textStart,
+			})
+		}
+	}
+	embed := func(start, end int, expr string) {
+		flush(start)
+		parts = append(parts, StringInterpolation{IsVariable: true, Value: expr, Offset: start})
+		textStart = end
+	}
 
-				parts = append(parts, StringInterpolation{
-					IsVariable: true,
-					Value:      input[varStart:i],
-				})
+	for i := 0; i < len(raw); {
+		ch := raw[i]
+		switch {
+		case ch == '\\' && i+1 < len(raw):
+			i += 2
+			continue
+
+		case ch == '$' && i+1 < len(raw) && isNameStart(raw[i+1]):
+			expr, end := scanSimpleInterpolation(raw, i)
+			embed(i, end, expr)
+			i = end
+			continue
+
+		case ch == '$' && i+1 < len(raw) && raw[i+1] == '{':
+			if end := matchBrace(raw, i+1); end > 0 {
+				embed(i, end+1, dollarBraceExpression(raw[i+2:end]))
+				i = end + 1
 				continue
-			} else if next == '{' {
-				// Complex interpolation like ${expr} or {$expr}
-				// For now, treat as regular text (will be enhanced later)
-				current.WriteByte(ch)
-				i++
+			}
+
+		case ch == '{' && i+1 < len(raw) && raw[i+1] == '$':
+			if end := matchBrace(raw, i); end > 0 {
+				embed(i, end+1, raw[i+1:end])
+				i = end + 1
 				continue
 			}
 		}
+		i++
+	}
 
-		current.WriteByte(ch)
+	flush(len(raw))
+	return parts
+}
+
+// scanSimpleInterpolation scans the simple syntax starting at the $ at
+// raw[start]: a variable optionally followed by one [offset] or ->property.
+// It returns the equivalent expression source and the end offset.
+func scanSimpleInterpolation(raw string, start int) (string, int) {
+	i := start + 1
+	for i < len(raw) && isNameChar(raw[i]) {
 		i++
 	}
+	name := raw[start:i]
 
-	// Add any remaining text
-	if current.Len() > 0 {
-		parts = append(parts, StringInterpolation{
-			IsVariable: false,
-			Value:      current.String(),
-		})
+	if i < len(raw) && raw[i] == '[' {
+		if key, end, ok := scanSimpleOffset(raw, i+1); ok {
+			return name + "[" + key + "]", end
+		}
+	}
+	if i+2 < len(raw) && raw[i] == '-' && raw[i+1] == '>' && isNameStart(raw[i+2]) {
+		end := i + 2
+		for end < len(raw) && isNameChar(raw[end]) {
+			end++
+		}
+		return raw[start:end], end
 	}
+	return name, i
+}
 
-	return parts
+// scanSimpleOffset scans the offset of "$arr[key]" starting after the [.
+// Bare words are string keys, canonical integers are integer keys and
+// anything else numeric is a string key, as in PHP. It returns the key as
+// expression source and the offset after the closing ].
+func scanSimpleOffset(raw string, start int) (string, int, bool) {
+	i := start
+	var key string
+	switch {
+	case i+1 < len(raw) && raw[i] == '$' && isNameStart(raw[i+1]):
+		i++
+		for i < len(raw) && isNameChar(raw[i]) {
+			i++
+		}
+		key = raw[start:i]
+
+	case i < len(raw) && (isDigit(raw[i]) || raw[i] == '-'):
+		if raw[i] == '-' {
+			i++
+		}
+		digits := i
+		for i < len(raw) && isDigit(raw[i]) {
+			i++
+		}
+		if i == digits {
+			return "", 0, false
+		}
+		key = raw[start:i]
+		if _, err := strconv.ParseInt(key, 10, 64); err != nil ||
+			(raw[digits] == '0' && i-digits > 1) || key == "-0" {
+			key = "'" + key + "'"
+		}
+
+	case i < len(raw) && isNameStart(raw[i]):
+		for i < len(raw) && isNameChar(raw[i]) {
+			i++
+		}
+		key = "'" + raw[start:i] + "'"
+
+	default:
+		return "", 0, false
+	}
+
+	if i >= len(raw) || raw[i] != ']' {
+		return "", 0, false
+	}
+	return key, i + 1, true
+}
+
+// dollarBraceExpression converts the contents of "${...}" to expression
+// source: "${name}" and "${name[expr]}" name a variable, anything else is
+// a variable variable.
+func dollarBraceExpression(inner string) string {
+	i := 0
+	for i < len(inner) && isNameChar(inner[i]) {
+		i++
+	}
+	if i > 0 && isNameStart(inner[0]) && (i == len(inner) || inner[i] == '[') {
+		return "$" + inner
+	}
+	return "${" + inner + "}"
+}
+
+// matchBrace returns the offset of the } closing the { at s[open], or -1.
+// Quoted strings inside the braces are skipped.
+func matchBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case '\'', '"':
+			quote := s[i]
+			for i++; i < len(s) && s[i] != quote; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// unescapeString resolves the escape sequences of a double-quoted string
+// or heredoc: \n, \t, \r, \v, \e, \f, \\, \$, octal \0-\777, hex \xHH and
+// \u{codepoint}. An escaped quote is only resolved when quote is non-zero;
+// unknown sequences are kept as written.
+func unescapeString(raw string, quote byte) string {
+	if strings.IndexByte(raw, '\\') < 0 {
+		return raw
+	}
+
+	var result strings.Builder
+	for i := 0; i < len(raw); i++ {
+		ch := raw[i]
+		if ch != '\\' || i+1 == len(raw) {
+			result.WriteByte(ch)
+			continue
+		}
+
+		i++
+		switch next := raw[i]; {
+		case next == 'n':
+			result.WriteByte('\n')
+		case next == 't':
+			result.WriteByte('\t')
+		case next == 'r':
+			result.WriteByte('\r')
+		case next == 'v':
+			result.WriteByte('\v')
+		case next == 'e':
+			result.WriteByte(0x1b)
+		case next == 'f':
+			result.WriteByte('\f')
+		case next == '\\' || next == '$' || (next == quote && quote != 0):
+			result.WriteByte(next)
+		case next >= '0' && next <= '7':
+			value, j := 0, i
+			for j < len(raw) && j < i+3 && raw[j] >= '0' && raw[j] <= '7' {
+				value = value*8 + int(raw[j]-'0')
+				j++
+			}
+			result.WriteByte(byte(value))
+			i = j - 1
+		case next == 'x' && i+1 < len(raw) && isHexDigit(raw[i+1]):
+			value := hexValue(raw[i+1])
+			i++
+			if i+1 < len(raw) && isHexDigit(raw[i+1]) {
+				value = value*16 + hexValue(raw[i+1])
+				i++
+			}
+			result.WriteByte(value)
+		case next == 'u' && i+1 < len(raw) && raw[i+1] == '{':
+			if end := strings.IndexByte(raw[i:], '}'); end > 2 {
+				if r, err := strconv.ParseUint(raw[i+2:i+end], 16, 32); err == nil && r <= 0x10FFFF {
+					writeCodepoint(&result, uint32(r))
+					i += end
+					continue
+				}
+			}
+			result.WriteString("\\u")
+		default:
+			result.WriteByte('\\')
+			result.WriteByte(next)
+		}
+	}
+	return result.String()
+}
+
+// writeCodepoint writes a code point up to U+10FFFF encoded in UTF-8 the
+// way PHP does for \u{}: surrogates are encoded like any other code point
+// instead of being replaced, so the bytes are not always valid UTF-8
+func writeCodepoint(result *strings.Builder, r uint32) {
+	switch {
+	case r < 0x80:
+		result.WriteByte(byte(r))
+	case r < 0x800:
+		result.WriteByte(0xC0 | byte(r>>6))
+		result.WriteByte(0x80 | byte(r)&0x3F)
+	case r < 0x10000:
+		result.WriteByte(0xE0 | byte(r>>12))
+		result.WriteByte(0x80 | byte(r>>6)&0x3F)
+		result.WriteByte(0x80 | byte(r)&0x3F)
+	default:
+		result.WriteByte(0xF0 | byte(r>>18))
+		result.WriteByte(0x80 | byte(r>>12)&0x3F)
+		result.WriteByte(0x80 | byte(r>>6)&0x3F)
+		result.WriteByte(0x80 | byte(r)&0x3F)
+	}
 }
 
 // hasInterpolation checks if a string contains variable interpolation
 func hasInterpolation(s string) bool {
 	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			// Escaped characters never start an interpolation
+			i++
+			continue
+		}
 		if s[i] == '$' && i+1 < len(s) {
 			next := s[i+1]
-			if isLetter(byte(next)) || next == '_' || next == '{' {
+			if isNameStart(next) || next == '{' {
 				return true
 			}
 		}
@@ -280,6 +435,16 @@ func hasInterpolation(s string) bool {
 	return false
 }
 
+// isNameStart reports whether ch can start a variable or property name
+func isNameStart(ch byte) bool {
+	return isLetter(ch) || ch == '_'
+}
+
+// isNameChar reports whether ch can continue a variable or property name
+func isNameChar(ch byte) bool {
+	return isLetter(ch) || isDigit(ch) || ch == '_'
+}
+
 // isValidHeredocLabel checks if a string is a valid heredoc/nowdoc label
 func isValidHeredocLabel(label string) bool {
 	if len(label) == 0 {
@@ -303,69 +468,37 @@ func isValidHeredocLabel(label string) bool {
 	return true
 }
 
-// scanStringWithInterpolation scans a double-quoted string and handles interpolation
-// Returns ENCAPSED_START token if interpolation is found, otherwise returns STRING
+// scanStringWithInterpolation scans a double-quoted string. It returns a
+// STRING token with escape sequences resolved, or an ENCAPSED_START token
+// holding the raw body when the string embeds variables.
 func (l *Lexer) scanStringWithInterpolation() Token {
 	pos := l.currentPosition()
-	var result strings.Builder
+	var raw strings.Builder
 
 	l.readChar() // consume opening "
 
-	for l.ch != '"' && l.ch != 0 {
-		if l.ch == '\\' {
-			l.readChar()
-			switch l.ch {
-			case 'n':
-				result.WriteByte('\n')
-			case 't':
-				result.WriteByte('\t')
-			case 'r':
-				result.WriteByte('\r')
-			case '\\':
-				result.WriteByte('\\')
-			case '$':
-				result.WriteByte('$')
-			case '"':
-				result.WriteByte('"')
-			case '0':
-				result.WriteByte(0)
-			case 'x': // Hex escape \xHH
-				l.readChar()
-				hex1 := l.ch
-				l.readChar()
-				hex2 := l.ch
-				if isHexDigit(hex1) && isHexDigit(hex2) {
-					val := hexValue(hex1)*16 + hexValue(hex2)
-					result.WriteByte(byte(val))
-				} else {
-					result.WriteString("\\x")
-					result.WriteByte(hex1)
-					result.WriteByte(hex2)
-				}
-				l.readChar()
-				continue
-			default:
-				result.WriteByte('\\')
-				result.WriteByte(l.ch)
+	for l.ch != '"' && !l.eof() {
+		end := l.pos
+		if l.ch == '\\' && l.readPos < len(l.input) {
+			end = l.pos + 1
+		} else if l.ch == '{' && l.peekChar() == '$' {
+			// "{$expr}" is PHP code and may hold quotes, as in "{$a["k"]}"
+			if brace := matchBrace(l.input, l.pos); brace > 0 {
+				end = brace
 			}
-			l.readChar()
-		} else if l.ch == '$' && l.peekChar() != 0 {
-			// Check if this is a variable interpolation
-			// For now, just include the $ in the string
-			// Full interpolation support will be added later
-			result.WriteByte(l.ch)
-			l.readChar()
-		} else {
+		}
+
+		for l.pos <= end {
 			if l.ch == '\n' {
 				l.line++
 				l.column = 0
 			}
-			result.WriteByte(l.ch)
+			raw.WriteByte(l.ch)
 			l.readChar()
 		}
 	}
 
-	if l.ch == 0 {
+	if l.eof() {
 		return Token{
 			Type:    ILLEGAL,
 			Literal: "unterminated string",
@@ -376,11 +509,16 @@ func (l *Lexer) scanStringWithInterpolation() Token {
 	// Consume closing quote
 	l.readChar()
 
-	// For now, return STRING even if it has interpolation
-	// Full interpolation support will tokenize variables separately
+	if hasInterpolation(raw.String()) {
+		return Token{
+			Type:    ENCAPSED_START,
+			Literal: raw.String(),
+			Pos:     pos,
+		}
+	}
 	return Token{
 		Type:    STRING,
-		Literal: result.String(),
+		Literal: unescapeString(raw.String(), '"'),
 		Pos:     pos,
 	}
 }
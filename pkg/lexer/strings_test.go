@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -17,7 +18,7 @@ func TestHeredoc(t *testing.T) {
 Hello World
 EOT;`,
 			expectedType:    HEREDOC,
-			expectedLiteral: "Hello World\n",
+			expectedLiteral: "Hello World",
 		},
 		{
 			name: "heredoc with multiple lines",
@@ -27,7 +28,7 @@ Line 2
 Line 3
 EOT;`,
 			expectedType:    HEREDOC,
-			expectedLiteral: "Line 1\nLine 2\nLine 3\n",
+			expectedLiteral: "Line 1\nLine 2\nLine 3",
 		},
 		{
 			name: "heredoc with variables",
@@ -35,7 +36,7 @@ EOT;`,
 Hello $name
 EOT;`,
 			expectedType:    HEREDOC,
-			expectedLiteral: "Hello $name\n",
+			expectedLiteral: "Hello $name",
 		},
 		{
 			name: "heredoc without semicolon",
@@ -44,7 +45,7 @@ Hello
 EOT
 `,
 			expectedType:    HEREDOC,
-			expectedLiteral: "Hello\n",
+			expectedLiteral: "Hello",
 		},
 		{
 			name: "heredoc with different label",
@@ -52,7 +53,7 @@ EOT
 <div>Content</div>
 HTML;`,
 			expectedType:    HEREDOC,
-			expectedLiteral: "<div>Content</div>\n",
+			expectedLiteral: "<div>Content</div>",
 		},
 		{
 			name: "heredoc with indented content",
@@ -61,7 +62,7 @@ HTML;`,
         More indented
 EOT;`,
 			expectedType:    HEREDOC,
-			expectedLiteral: "    Indented line\n        More indented\n",
+			expectedLiteral: "    Indented line\n        More indented",
 		},
 	}
 
@@ -94,15 +95,15 @@ func TestNowdoc(t *testing.T) {
 Hello World
 EOT;`,
 			expectedType:    NOWDOC,
-			expectedLiteral: "Hello World\n",
+			expectedLiteral: "Hello World",
 		},
 		{
-			name: "nowdoc double quotes",
+			name: "double-quoted label is a heredoc",
 			input: `<<<"EOT"
 Hello World
 EOT;`,
-			expectedType:    NOWDOC,
-			expectedLiteral: "Hello World\n",
+			expectedType:    HEREDOC,
+			expectedLiteral: "Hello World",
 		},
 		{
 			name: "nowdoc with variables (not interpolated)",
@@ -111,7 +112,7 @@ Hello $name
 Price: $100
 EOT;`,
 			expectedType:    NOWDOC,
-			expectedLiteral: "Hello $name\nPrice: $100\n",
+			expectedLiteral: "Hello $name\nPrice: $100",
 		},
 		{
 			name: "nowdoc with backslashes",
@@ -119,7 +120,7 @@ EOT;`,
 Path: C:\Users\Name
 EOT;`,
 			expectedType:    NOWDOC,
-			expectedLiteral: "Path: C:\\Users\\Name\n",
+			expectedLiteral: "Path: C:\\Users\\Name",
 		},
 	}
 
@@ -171,6 +172,30 @@ Hello World
 			expectedType: ILLEGAL,
 			expectError:  true,
 		},
+		{
+			name:         "body indented less than closing tag",
+			input:        "<<<EOT\n  Hello\n    EOT;",
+			expectedType: ILLEGAL,
+			expectError:  true,
+		},
+		{
+			name:         "tabs and spaces mixed",
+			input:        "<<<EOT\n\t  Hello\n  EOT;",
+			expectedType: ILLEGAL,
+			expectError:  true,
+		},
+		{
+			name:         "label prefix is not a closing tag",
+			input:        "<<<EOT\nEOTX\n",
+			expectedType: ILLEGAL,
+			expectError:  true,
+		},
+		{
+			name:         "text after opening label",
+			input:        "<<<EOT Hello\nEOT;",
+			expectedType: ILLEGAL,
+			expectError:  true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -201,14 +226,25 @@ func TestHeredocIndented(t *testing.T) {
 		{
 			name: "indented closing tag with spaces",
 			input: `<<<EOT
-Hello
+    Hello
+      World
     EOT;`,
-			expectedLiteral: "Hello\n",
+			expectedLiteral: "Hello\n  World",
 		},
 		{
-			name: "indented closing tag with tabs",
-			input: "<<<EOT\nHello\n\tEOT;",
-			expectedLiteral: "Hello\n",
+			name:            "indented closing tag with tabs",
+			input:           "<<<EOT\n\tHello\n\t\tWorld\n\tEOT;",
+			expectedLiteral: "Hello\n\tWorld",
+		},
+		{
+			name:            "blank lines may be indented less",
+			input:           "<<<EOT\n    a\n\n  \n    b\n    EOT;",
+			expectedLiteral: "a\n\n\nb",
+		},
+		{
+			name:            "interpolation after the indentation",
+			input:           "<<<EOT\n  $name\n  {$a['k']}\n  EOT;",
+			expectedLiteral: "$name\n{$a['k']}",
 		},
 	}
 
@@ -273,11 +309,70 @@ func TestStringInterpolation(t *testing.T) {
 				{IsVariable: false, Value: "Price: $100"},
 			},
 		},
+		{
+			name:  "array offsets",
+			input: "$a[key] $a[0] $a[-1] $a[01] $a[$i]",
+			expected: []StringInterpolation{
+				{IsVariable: true, Value: "$a['key']"},
+				{IsVariable: false, Value: " "},
+				{IsVariable: true, Value: "$a[0]"},
+				{IsVariable: false, Value: " "},
+				{IsVariable: true, Value: "$a[-1]"},
+				{IsVariable: false, Value: " "},
+				{IsVariable: true, Value: "$a['01']"},
+				{IsVariable: false, Value: " "},
+				{IsVariable: true, Value: "$a[$i]"},
+			},
+		},
+		{
+			name:  "property",
+			input: "$obj->name->first",
+			expected: []StringInterpolation{
+				{IsVariable: true, Value: "$obj->name"},
+				{IsVariable: false, Value: "->first"},
+			},
+		},
+		{
+			name:  "complex syntax",
+			input: `{$a['k']['j']}s and {$obj->get("}")}`,
+			expected: []StringInterpolation{
+				{IsVariable: true, Value: "$a['k']['j']"},
+				{IsVariable: false, Value: "s and "},
+				{IsVariable: true, Value: "$obj->get(\"}\")"},
+			},
+		},
+		{
+			name:  "dollar brace syntax",
+			input: "${name} ${arr['k']} ${$n}",
+			expected: []StringInterpolation{
+				{IsVariable: true, Value: "$name"},
+				{IsVariable: false, Value: " "},
+				{IsVariable: true, Value: "$arr['k']"},
+				{IsVariable: false, Value: " "},
+				{IsVariable: true, Value: "${$n}"},
+			},
+		},
+		{
+			name:  "escapes in text",
+			input: `\$a\t\101\x42\u{263A}\"{ $b`,
+			expected: []StringInterpolation{
+				{IsVariable: false, Value: "$a\tAB\u263A\"{ "},
+				{IsVariable: true, Value: "$b"},
+			},
+		},
+		{
+			name:  "unterminated brace is text",
+			input: "{$a",
+			expected: []StringInterpolation{
+				{IsVariable: false, Value: "{"},
+				{IsVariable: true, Value: "$a"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := parseStringInterpolation(tt.input)
+			result := SplitInterpolation(tt.input, '"')
 
 			if len(result) != len(tt.expected) {
 				t.Errorf("wrong number of parts. expected=%d, got=%d", len(tt.expected), len(result))
@@ -311,6 +406,8 @@ func TestHasInterpolation(t *testing.T) {
 		{"Test {$expr}", true},
 		{"$", false},
 		{"$$var", true},
+		{`\$var`, false},
+		{`\\$var`, true},
 	}
 
 	for _, tt := range tests {
@@ -435,7 +532,7 @@ echo $text;
 		{OPEN_TAG, "<?php"},
 		{VARIABLE, "$text"},
 		{ASSIGN, "="},
-		{HEREDOC, "This is a heredoc string\nwith multiple lines\nand some $variables\n"},
+		{HEREDOC, "This is a heredoc string\nwith multiple lines\nand some $variables"},
 		{SEMICOLON, ";"},
 		{ECHO, "echo"},
 		{VARIABLE, "$text"},
@@ -480,7 +577,7 @@ func BenchmarkStringInterpolation(b *testing.B) {
 	input := "Hello $name, you have $count messages"
 
 	for i := 0; i < b.N; i++ {
-		parseStringInterpolation(input)
+		SplitInterpolation(input, '"')
 	}
 }
 
@@ -553,15 +650,40 @@ func TestStringEscapeSequences(t *testing.T) {
 			expectedLiteral: "\\xGH",
 		},
 		{
-			name:            "invalid hex escape - one digit",
+			name:            "hex escape - one digit",
 			input:           `"\x4Z"`,
-			expectedLiteral: "\\x4Z",
+			expectedLiteral: "\x04Z",
 		},
 		{
 			name:            "mixed escapes",
 			input:           `"line1\nline2\ttab\x41"`,
 			expectedLiteral: "line1\nline2\ttabA",
 		},
+		{
+			name:            "octal escapes",
+			input:           `"\101\7\400"`,
+			expectedLiteral: "A\x07\x00",
+		},
+		{
+			name:            "invalid UTF-8 from hex escapes",
+			input:           `"\xff\xfe\x00"`,
+			expectedLiteral: "\xff\xfe\x00",
+		},
+		{
+			name:            "unicode codepoint escapes",
+			input:           `"\u{41}\u{e9}\u{20AC}\u{1F600}"`,
+			expectedLiteral: "A\u00e9\u20ac\U0001F600",
+		},
+		{
+			name:            "unicode surrogate escape",
+			input:           `"\u{D800}"`,
+			expectedLiteral: "\xed\xa0\x80",
+		},
+		{
+			name:            "raw bytes",
+			input:           "\"a\x00b\xffc\"",
+			expectedLiteral: "a\x00b\xffc",
+		},
 	}
 
 	for _, tt := range tests {
@@ -580,6 +702,23 @@ func TestStringEscapeSequences(t *testing.T) {
 	}
 }
 
+func TestStringNulBytes(t *testing.T) {
+	// NUL bytes are data in strings, not the end of the input
+	for _, input := range []string{"'a\x00b'", "\"a\x00b\"", "<<<'EOT'\na\x00b\nEOT\n"} {
+		tok := New(input, "test.php").NextToken()
+		if !strings.Contains(tok.Literal, "a\x00b") {
+			t.Errorf("%q: expected a NUL byte in the literal, got %s %q", input, tok.Type, tok.Literal)
+		}
+	}
+
+	// Outside strings a NUL byte is illegal rather than the end of the file
+	l := New("1\x00;", "test.php")
+	l.NextToken()
+	if tok := l.NextToken(); tok.Type != ILLEGAL {
+		t.Errorf("Expected ILLEGAL for a NUL byte, got %s", tok.Type)
+	}
+}
+
 func TestUnterminatedString(t *testing.T) {
 	input := `"unterminated string`
 	l := New(input, "test.php")
@@ -618,23 +757,45 @@ func TestStringWithDollarSign(t *testing.T) {
 	tests := []struct {
 		name            string
 		input           string
+		expectedType    TokenType
 		expectedLiteral string
 	}{
 		{
 			name:            "dollar sign alone",
 			input:           `"price $"`,
+			expectedType:    STRING,
 			expectedLiteral: "price $",
 		},
 		{
 			name:            "dollar sign with text",
 			input:           `"total $amount"`,
+			expectedType:    ENCAPSED_START,
 			expectedLiteral: "total $amount",
 		},
 		{
 			name:            "multiple dollar signs",
 			input:           `"$a $b $c"`,
+			expectedType:    ENCAPSED_START,
 			expectedLiteral: "$a $b $c",
 		},
+		{
+			name:            "escaped dollar sign",
+			input:           `"total \$amount"`,
+			expectedType:    STRING,
+			expectedLiteral: "total $amount",
+		},
+		{
+			name:            "escapes kept raw when interpolating",
+			input:           `"\t$a\n"`,
+			expectedType:    ENCAPSED_START,
+			expectedLiteral: `\t$a\n`,
+		},
+		{
+			name:            "quotes inside braces",
+			input:           `"v: {$a["k"]}"`,
+			expectedType:    ENCAPSED_START,
+			expectedLiteral: `v: {$a["k"]}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -642,8 +803,8 @@ func TestStringWithDollarSign(t *testing.T) {
 			l := New(tt.input, "test.php")
 			tok := l.NextToken()
 
-			if tok.Type != STRING {
-				t.Errorf("token type wrong. expected=STRING, got=%q", tok.Type)
+			if tok.Type != tt.expectedType {
+				t.Errorf("token type wrong. expected=%q, got=%q", tt.expectedType, tok.Type)
 			}
 
 			if tok.Literal != tt.expectedLiteral {
@@ -651,6 +812,12 @@ func TestStringWithDollarSign(t *testing.T) {
 			}
 		})
 	}
+
+	l := New(`"{$a["k"]}";`, "test.php")
+	l.NextToken()
+	if tok := l.NextToken(); tok.Type != SEMICOLON {
+		t.Errorf("expected SEMICOLON after string, got %q", tok.Type)
+	}
 }
 
 func TestHeredocVariations(t *testing.T) {
@@ -666,7 +833,7 @@ line1
 
 line3
 EOT;`,
-			expectedLiteral: "line1\n\nline3\n",
+			expectedLiteral: "line1\n\nline3",
 		},
 		{
 			name: "heredoc with only newlines",
@@ -674,14 +841,14 @@ EOT;`,
 
 
 EOT;`,
-			expectedLiteral: "\n\n",
+			expectedLiteral: "\n",
 		},
 		{
 			name: "heredoc with trailing spaces",
 			input: `<<<EOT
 text
 EOT;`,
-			expectedLiteral: "text\n",
+			expectedLiteral: "text",
 		},
 	}
 
@@ -713,14 +880,14 @@ func TestNowdocVariations(t *testing.T) {
 Hello $name
 Total $count
 EOT;`,
-			expectedLiteral: "Hello $name\nTotal $count\n",
+			expectedLiteral: "Hello $name\nTotal $count",
 		},
 		{
 			name: "nowdoc with backslashes",
 			input: `<<<'EOT'
 C:\path\to\file
 EOT;`,
-			expectedLiteral: "C:\\path\\to\\file\n",
+			expectedLiteral: "C:\\path\\to\\file",
 		},
 	}
 
@@ -739,3 +906,46 @@ EOT;`,
 		})
 	}
 }
+
+func TestHeredocClosingTagFollowedByCode(t *testing.T) {
+	input := `<?php
+foo(<<<EOT
+  a
+  b
+  EOT, <<<'NOW'
+  $c
+  NOW);
+$x;`
+
+	expectedTokens := []struct {
+		tokenType TokenType
+		literal   string
+		line      int
+	}{
+		{OPEN_TAG, "<?php", 1},
+		{IDENT, "foo", 2},
+		{LPAREN, "(", 2},
+		{HEREDOC, "a\nb", 2},
+		{COMMA, ",", 5},
+		{NOWDOC, "$c", 5},
+		{RPAREN, ")", 7},
+		{SEMICOLON, ";", 7},
+		{VARIABLE, "$x", 8},
+	}
+
+	l := New(input, "test.php")
+	for i, expected := range expectedTokens {
+		tok := l.NextToken()
+
+		if tok.Type != expected.tokenType {
+			t.Fatalf("token[%d] type wrong. expected=%q, got=%q (%q)",
+				i, expected.tokenType, tok.Type, tok.Literal)
+		}
+		if tok.Literal != expected.literal {
+			t.Errorf("token[%d] literal wrong. expected=%q, got=%q", i, expected.literal, tok.Literal)
+		}
+		if tok.Pos.Line != expected.line {
+			t.Errorf("token[%d] line wrong. expected=%d, got=%d", i, expected.line, tok.Pos.Line)
+		}
+	}
+}
@@ -10,6 +10,8 @@ type Token struct {
 	Type    TokenType // Token type
 	Literal string    // Actual text of the token
 	Pos     Position  // Position in source code
+	End     Position  // Position just past the token
+	Leading []Token   // Trivia before the token, in trivia mode
 }
 
 // Position represents a location in the source code
@@ -26,6 +28,7 @@ const (
 	ILLEGAL TokenType = iota // Illegal/unknown token
 	EOF                      // End of file
 	COMMENT                  // Comment (single-line or multi-line)
+	DOC_COMMENT              // Doc comment: /** ... */
 
 	// Literals
 	INTEGER        // 123, 0x1A, 0b1010, 0o777
@@ -33,7 +36,7 @@ const (
 	STRING         // "string", 'string'
 	HEREDOC        // <<<EOT ... EOT
 	NOWDOC         // <<<'EOT' ... EOT
-	ENCAPSED_START // Start of string interpolation
+	ENCAPSED_START // "..." with interpolation; the literal is the raw body
 	ENCAPSED_END   // End of string interpolation
 
 	// Identifiers and variables
@@ -224,6 +227,7 @@ const (
 
 	// Whitespace
 	WHITESPACE        // Space, tab, newline
+	INLINE_HTML       // Text outside of PHP tags
 )
 
 // String returns a human-readable representation of the token
@@ -241,6 +245,7 @@ var tokenNames = map[TokenType]string{
 	ILLEGAL:           "ILLEGAL",
 	EOF:               "EOF",
 	COMMENT:           "COMMENT",
+	DOC_COMMENT:       "DOC_COMMENT",
 
 	INTEGER:           "INTEGER",
 	FLOAT:             "FLOAT",
@@ -429,6 +434,7 @@ var tokenNames = map[TokenType]string{
 	ATTRIBUTE_START:   "#[",
 
 	WHITESPACE:        "WHITESPACE",
+	INLINE_HTML:       "INLINE_HTML",
 }
 
 // keywords maps PHP keywords to their token types
@@ -447,6 +453,7 @@ var keywords = map[string]TokenType{
 	"continue":      CONTINUE,
 	"declare":       DECLARE,
 	"default":       DEFAULT,
+	"die":           EXIT,
 	"do":            DO,
 	"echo":          ECHO,
 	"else":          ELSE,
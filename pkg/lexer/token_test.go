@@ -396,7 +396,7 @@ func TestPositionComparison(t *testing.T) {
 func TestAllTokenTypesHaveNames(t *testing.T) {
 	// Test that all token types have corresponding names
 	// This helps catch missing entries in the tokenNames map
-	for i := TokenType(0); i <= WHITESPACE; i++ {
+	for i := TokenType(0); i <= INLINE_HTML; i++ {
 		name := i.String()
 		if name == "" {
 			t.Errorf("TokenType %d is missing a name in tokenNames map", i)
@@ -0,0 +1,95 @@
+package lexer
+
+import "strings"
+
+// KeepTrivia switches the lexer to trivia mode for tools that reproduce
+// the source exactly, such as formatters and refactoring tools. In this
+// mode whitespace, comments, opening tags and inline HTML are not skipped
+// or returned as tokens: they are attached to the token they precede as
+// its Leading trivia, the end of the input to the EOF token. A closing tag
+// "?>" ends a statement, and "<?=" starts one, so they are returned as
+// SEMICOLON and ECHO tokens with their own literals.
+//
+// Input containing an opening tag starts outside of PHP, as a file does;
+// input without one is code, as in the default mode. KeepTrivia must be
+// called before the first token is read.
+func (l *Lexer) KeepTrivia() {
+	l.trivia = true
+	l.html = l.base == 0 && strings.Contains(l.input, "<?")
+}
+
+// KeepsTrivia reports whether the lexer is in trivia mode
+func (l *Lexer) KeepsTrivia() bool {
+	return l.trivia
+}
+
+// nextWithTrivia returns the next token with the trivia before it
+func (l *Lexer) nextWithTrivia() Token {
+	var leading []Token
+	for {
+		if l.html {
+			if html := l.scanInlineHTML(); html.Literal != "" {
+				leading = append(leading, html)
+			}
+			l.html = false
+		}
+		if ws := l.scanWhitespace(); ws.Literal != "" {
+			leading = append(leading, ws)
+		}
+
+		tok := l.nextToken()
+		switch tok.Type {
+		case COMMENT, DOC_COMMENT, OPEN_TAG:
+			leading = append(leading, tok)
+			continue
+		case OPEN_TAG_ECHO:
+			tok.Type = ECHO
+		case CLOSE_TAG:
+			tok.Type = SEMICOLON
+			l.html = true
+		case EOF:
+			// Scanning stops at a NUL byte; the rest of the input is
+			// illegal trivia
+			if rest := l.base + len(l.input) - tok.Pos.Offset; rest > 0 {
+				end := tok.Pos
+				end.Offset += rest
+				leading = append(leading, Token{Type: ILLEGAL, Literal: l.input[tok.Pos.Offset-l.base:], Pos: tok.Pos, End: end})
+				l.pos, l.readPos, l.ch = len(l.input), len(l.input)+1, 0
+				tok.Pos, tok.End = end, end
+			}
+		}
+		tok.Leading = leading
+		return tok
+	}
+}
+
+// scanWhitespace scans a run of whitespace as a WHITESPACE token, whose
+// literal is empty if there is none
+func (l *Lexer) scanWhitespace() Token {
+	if l.pos >= len(l.input) {
+		return Token{} // Past the end, after EOF
+	}
+	pos := l.currentPosition()
+	start := l.pos
+	l.skipWhitespace()
+	return Token{Type: WHITESPACE, Literal: l.input[start:l.pos], Pos: pos, End: l.currentPosition()}
+}
+
+// scanInlineHTML scans the text up to the next opening tag as an
+// INLINE_HTML token, whose literal is empty if there is none
+func (l *Lexer) scanInlineHTML() Token {
+	if l.pos >= len(l.input) {
+		return Token{} // Past the end, after EOF
+	}
+	pos := l.currentPosition()
+	start := l.pos
+	for !l.eof() && !(l.ch == '<' && l.peekChar() == '?') {
+		if l.ch == '\n' {
+			l.line++
+			l.column = 0
+			l.lineStart = l.pos + 1
+		}
+		l.readChar()
+	}
+	return Token{Type: INLINE_HTML, Literal: l.input[start:l.pos], Pos: pos, End: l.currentPosition()}
+}
@@ -0,0 +1,90 @@
+package lexer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestTrivia(t *testing.T) {
+	input := "<p>\n<?php\n/** Doc */\nfunction f() {} // end\n?>\n<b><?= $x ?>"
+	l := New(input, "test.php")
+	l.KeepTrivia()
+
+	// Each token as "trivia... | type literal"
+	var got []string
+	for {
+		tok := l.NextToken()
+		s := ""
+		for _, trivia := range tok.Leading {
+			s += fmt.Sprintf("%s %q ", trivia.Type, trivia.Literal)
+		}
+		got = append(got, fmt.Sprintf("%s| %s %q", s, tok.Type, tok.Literal))
+		if tok.Type == EOF {
+			break
+		}
+	}
+	expected := []string{
+		`INLINE_HTML "<p>\n" <?php "<?php" WHITESPACE "\n" DOC_COMMENT "/** Doc */" WHITESPACE "\n" | FUNCTION "function"`,
+		`WHITESPACE " " | IDENT "f"`,
+		`| ( "("`,
+		`| ) ")"`,
+		`WHITESPACE " " | { "{"`,
+		`| } "}"`,
+		`WHITESPACE " " COMMENT "// end" WHITESPACE "\n" | ; "?>"`,
+		`INLINE_HTML "\n<b>" | ECHO "<?="`,
+		`WHITESPACE " " | VARIABLE "$x"`,
+		`WHITESPACE " " | ; "?>"`,
+		`| EOF ""`,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected tokens\n%v\ngot\n%v", expected, got)
+	}
+}
+
+func TestTrivia_Positions(t *testing.T) {
+	// Trivia and tokens cover the input exactly, with correct lines
+	input := "<?php\n/* a\nb */ $x =\t'y';\n\x00rest"
+	l := New(input, "test.php")
+	l.KeepTrivia()
+
+	offset := 0
+	cover := func(tok Token) {
+		if tok.Pos.Offset != offset {
+			t.Fatalf("%s starts at %d, expected %d", tok, tok.Pos.Offset, offset)
+		}
+		offset = tok.End.Offset
+	}
+	var x Token
+	for {
+		tok := l.NextToken()
+		for _, trivia := range tok.Leading {
+			cover(trivia)
+		}
+		cover(tok)
+		if tok.Type == VARIABLE {
+			x = tok
+		}
+		if tok.Type == EOF {
+			break
+		}
+	}
+	if offset != len(input) {
+		t.Errorf("Expected the tokens to end at %d, got %d", len(input), offset)
+	}
+	if x.Pos.Line != 3 || x.Pos.Column != 6 {
+		t.Errorf("Expected $x at 3:6, got %d:%d", x.Pos.Line, x.Pos.Column)
+	}
+}
+
+func TestTrivia_Code(t *testing.T) {
+	// Input without an opening tag is code, as in the default mode
+	l := New("$a // b", "test.php")
+	l.KeepTrivia()
+	if tok := l.NextToken(); tok.Type != VARIABLE || len(tok.Leading) != 0 {
+		t.Errorf("Expected $a without trivia, got %s with %v", tok, tok.Leading)
+	}
+	if tok := l.NextToken(); tok.Type != EOF || len(tok.Leading) != 2 || tok.Leading[1].Type != COMMENT {
+		t.Errorf("Expected EOF after a comment, got %s with %v", tok, tok.Leading)
+	}
+}
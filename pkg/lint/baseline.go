@@ -0,0 +1,92 @@
+package lint
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/krizos/php-go/pkg/diagnostic"
+)
+
+// Baseline records the findings a project accepts, for the analyzer to
+// report only new ones. Findings are matched by file, code and message,
+// not by line, so that editing a file does not invalidate its entries.
+type Baseline struct {
+	Entries []BaselineEntry `json:"entries"`
+}
+
+// BaselineEntry is the number of times a finding is accepted in a file
+type BaselineEntry struct {
+	File    string `json:"file"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// baselineKey identifies the findings a baseline entry accepts
+type baselineKey struct {
+	file, code, message string
+}
+
+func keyOf(d diagnostic.Diagnostic) baselineKey {
+	return baselineKey{filepath.ToSlash(d.File), d.Code, d.Message}
+}
+
+// NewBaseline creates a baseline accepting the given findings
+func NewBaseline(diagnostics []diagnostic.Diagnostic) *Baseline {
+	counts := make(map[baselineKey]int)
+	for _, d := range diagnostics {
+		counts[keyOf(d)]++
+	}
+	b := &Baseline{Entries: []BaselineEntry{}}
+	for key, count := range counts {
+		b.Entries = append(b.Entries, BaselineEntry{File: key.file, Code: key.code, Message: key.message, Count: count})
+	}
+	sort.Slice(b.Entries, func(i, j int) bool {
+		x, y := b.Entries[i], b.Entries[j]
+		if x.File != y.File {
+			return x.File < y.File
+		}
+		if x.Code != y.Code {
+			return x.Code < y.Code
+		}
+		return x.Message < y.Message
+	})
+	return b
+}
+
+// ReadBaseline reads a baseline written by Write
+func ReadBaseline(r io.Reader) (*Baseline, error) {
+	var b Baseline
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Write writes the baseline as a JSON document
+func (b *Baseline) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(b)
+}
+
+// Filter returns the findings the baseline does not accept. An entry
+// accepts as many findings as its count, the first ones in order.
+func (b *Baseline) Filter(diagnostics []diagnostic.Diagnostic) []diagnostic.Diagnostic {
+	remaining := make(map[baselineKey]int)
+	for _, e := range b.Entries {
+		remaining[baselineKey{e.File, e.Code, e.Message}] += e.Count
+	}
+	var filtered []diagnostic.Diagnostic
+	for _, d := range diagnostics {
+		key := keyOf(d)
+		if remaining[key] > 0 {
+			remaining[key]--
+			continue
+		}
+		filtered = append(filtered, d)
+	}
+	return filtered
+}
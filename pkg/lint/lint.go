@@ -0,0 +1,334 @@
+// Package lint finds likely mistakes in PHP code without running it:
+// variables read where no path assigns them, unreachable statements,
+// private members nothing uses, references to functions and classes the
+// project does not declare, calls with the wrong number of arguments and
+// conditions that are always true or false.
+//
+// The files of a project are analyzed together, so that what one file
+// declares is known in the others. Each file is also compiled, and the
+// constructs the compiler rejects are reported.
+package lint
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/compiler"
+	"github.com/krizos/php-go/pkg/diagnostic"
+	"github.com/krizos/php-go/pkg/lexer"
+	"github.com/krizos/php-go/pkg/parser"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// Rule is a check of the analyzer. The diagnostics of a rule have its
+// name as their code.
+type Rule string
+
+const (
+	RuleUndefinedVariable Rule = "undefined-variable" // Read where no path assigns it
+	RuleUnreachableCode   Rule = "unreachable-code"   // After return, throw, break, continue or exit
+	RuleUnusedPrivate     Rule = "unused-private"     // Private method or property never used
+	RuleUndefinedFunction Rule = "undefined-function" // Called but declared nowhere
+	RuleUndefinedClass    Rule = "undefined-class"    // Instantiated, extended or accessed but declared nowhere
+	RuleArgumentCount     Rule = "argument-count"     // Too few or too many arguments
+	RuleConstantCondition Rule = "constant-condition" // Condition always true or always false
+)
+
+// Rules lists the rules of the analyzer with what they report
+var Rules = []struct {
+	Rule        Rule
+	Description string
+}{
+	{RuleUndefinedVariable, "variables read where no path assigns them"},
+	{RuleUnreachableCode, "statements after return, throw, break, continue or exit"},
+	{RuleUnusedPrivate, "private methods and properties their class never uses"},
+	{RuleUndefinedFunction, "calls to functions declared neither in the project nor built in"},
+	{RuleUndefinedClass, "references to classes declared neither in the project nor built in"},
+	{RuleArgumentCount, "calls to project functions and methods with too few or too many arguments"},
+	{RuleConstantCondition, "conditions that are always true or always false"},
+}
+
+// IsRule reports whether name is the name of a rule
+func IsRule(name string) bool {
+	for _, r := range Rules {
+		if string(r.Rule) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Options selects the rules to run: those enabled, or all of them if none
+// is, except those disabled
+type Options struct {
+	Enable  []Rule
+	Disable []Rule
+}
+
+// runs reports whether the options select a rule
+func (o Options) runs(rule Rule) bool {
+	for _, r := range o.Disable {
+		if r == rule {
+			return false
+		}
+	}
+	if len(o.Enable) == 0 {
+		return true
+	}
+	for _, r := range o.Enable {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// Project is a set of files analyzed together
+type Project struct {
+	files     []*file
+	functions map[string][]*function // By lowercased fully qualified name
+	classes   map[string][]*class    // By lowercased fully qualified name
+
+	// guarded holds the lowercased names tested with function_exists,
+	// class_exists and the like: code using them knows they may be missing
+	guarded map[string]bool
+
+	// natives declares the built-in functions and classes
+	natives *vm.VM
+}
+
+// file is a file of the project, with its syntax and compile errors
+type file struct {
+	name        string
+	program     *ast.Program // nil if the file does not parse
+	diagnostics []diagnostic.Diagnostic
+}
+
+// function is a function the project declares
+type function struct {
+	name   string // Fully qualified
+	params []*ast.Parameter
+}
+
+// class is a class-like declaration of the project
+type class struct {
+	name      string // Fully qualified
+	parent    string // Fully qualified name of the parent class, "" if none
+	methods   map[string]*ast.MethodDeclaration
+	usesTrait bool // Trait methods may be added to those declared
+}
+
+// NewProject creates an empty project
+func NewProject() *Project {
+	return &Project{
+		functions: make(map[string][]*function),
+		classes:   make(map[string][]*class),
+		guarded:   make(map[string]bool),
+		natives:   vm.New(),
+	}
+}
+
+// AddFile parses and compiles a file of the project and indexes its
+// declarations
+func (p *Project) AddFile(name string, source []byte) {
+	f := &file{name: name}
+	p.files = append(p.files, f)
+
+	ps := parser.New(lexer.New(string(source), name))
+	program := ps.ParseProgram()
+	if ps.HasErrors() {
+		f.diagnostics = diagnostic.FromParser(ps)
+		return
+	}
+	f.program = program
+
+	c := compiler.New()
+	c.SetFile(name)
+	if err := c.Compile(program); err != nil {
+		f.diagnostics = append(f.diagnostics, diagnostic.FromCompileError(name, err))
+	}
+	p.declare(program)
+}
+
+// Lint analyzes the files of the project, returning the syntax and
+// compile errors of each followed by what the rules found, sorted by
+// position
+func (p *Project) Lint(opts Options) []diagnostic.Diagnostic {
+	var diagnostics []diagnostic.Diagnostic
+	for _, f := range p.files {
+		diagnostics = append(diagnostics, f.diagnostics...)
+		if f.program == nil {
+			continue
+		}
+		c := &checker{project: p, opts: opts, file: f.name, ns: compiler.NewNamespaceContext("")}
+		c.statements(f.program.Statements)
+		if opts.runs(RuleUndefinedVariable) {
+			c.ns = compiler.NewNamespaceContext("")
+			c.fileScope(f.program.Statements)
+		}
+		sort.SliceStable(c.diagnostics, func(i, j int) bool {
+			a, b := c.diagnostics[i].Range.Start, c.diagnostics[j].Range.Start
+			return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+		})
+		diagnostics = append(diagnostics, c.diagnostics...)
+	}
+	return diagnostics
+}
+
+// ============================================================================
+// Declarations
+// ============================================================================
+
+// declare indexes the functions and classes a program declares, at any
+// depth, and the names it tests the existence of
+func (p *Project) declare(program *ast.Program) {
+	ns := compiler.NewNamespaceContext("")
+	var visit func(node ast.Node) bool
+	visit = func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.NamespaceStatement:
+			ns = compiler.NewNamespaceContext(n.Name)
+			if n.Body != nil {
+				ast.Inspect(n.Body, visit)
+				ns = compiler.NewNamespaceContext("")
+				return false
+			}
+		case *ast.UseStatement:
+			for _, item := range n.Items {
+				ns.AddUse(item.Kind, item.Name, item.Alias)
+			}
+		case *ast.FunctionDeclaration:
+			name := qualify(ns, n.Name.Value)
+			key := strings.ToLower(name)
+			p.functions[key] = append(p.functions[key], &function{name: name, params: n.Parameters})
+		case *ast.ClassDeclaration:
+			cls := p.addClass(qualify(ns, n.Name.Value), n.Body)
+			if n.Extends != nil {
+				cls.parent = ns.ResolveClassName(n.Extends.Value)
+			}
+		case *ast.InterfaceDeclaration:
+			p.addClass(qualify(ns, n.Name.Value), nil)
+		case *ast.TraitDeclaration:
+			p.addClass(qualify(ns, n.Name.Value), n.Body)
+		case *ast.EnumDeclaration:
+			p.addClass(qualify(ns, n.Name.Value), n.Body)
+		case *ast.CallExpression:
+			if ident, ok := n.Function.(*ast.Identifier); ok && existenceTest(ident.Value) && len(n.Arguments) > 0 {
+				if name, ok := n.Arguments[0].(*ast.StringLiteral); ok {
+					p.guarded[strings.ToLower(strings.TrimPrefix(name.Value, "\\"))] = true
+				}
+			}
+		}
+		return true
+	}
+	for _, stmt := range program.Statements {
+		ast.Inspect(stmt, visit)
+	}
+}
+
+// addClass indexes a class-like declaration with the methods of its body
+func (p *Project) addClass(name string, body []ast.Stmt) *class {
+	cls := &class{name: name, methods: make(map[string]*ast.MethodDeclaration)}
+	for _, stmt := range body {
+		switch s := stmt.(type) {
+		case *ast.MethodDeclaration:
+			cls.methods[strings.ToLower(s.Name.Value)] = s
+		case *ast.TraitUse:
+			cls.usesTrait = true
+		}
+	}
+	key := strings.ToLower(name)
+	p.classes[key] = append(p.classes[key], cls)
+	return cls
+}
+
+// existenceTest reports whether a function tests whether a function or
+// class exists
+func existenceTest(name string) bool {
+	switch strings.ToLower(strings.TrimPrefix(name, "\\")) {
+	case "function_exists", "class_exists", "interface_exists", "trait_exists", "enum_exists", "is_callable":
+		return true
+	}
+	return false
+}
+
+// qualify prefixes a declared name with the namespace
+func qualify(ns *compiler.NamespaceContext, name string) string {
+	if ns.Name() == "" {
+		return name
+	}
+	return ns.Name() + "\\" + name
+}
+
+// function returns the only function of a fully qualified name, or nil
+// if there is none or several, conditionally declared
+func (p *Project) function(name string) *function {
+	if fns := p.functions[strings.ToLower(name)]; len(fns) == 1 {
+		return fns[0]
+	}
+	return nil
+}
+
+// class returns the only class of a fully qualified name, or nil
+func (p *Project) class(name string) *class {
+	if classes := p.classes[strings.ToLower(name)]; len(classes) == 1 {
+		return classes[0]
+	}
+	return nil
+}
+
+// method looks a method up in a class and its parents. It returns nil if
+// the class or a parent is unknown, or a trait may declare the method.
+func (p *Project) method(cls *class, name string) (*ast.MethodDeclaration, *class) {
+	for cls != nil {
+		if m, ok := cls.methods[strings.ToLower(name)]; ok {
+			return m, cls
+		}
+		if cls.usesTrait || cls.parent == "" {
+			return nil, nil
+		}
+		cls = p.class(cls.parent)
+	}
+	return nil, nil
+}
+
+// functionExists reports whether a function is declared, in the project or
+// built in, or tested for
+func (p *Project) functionExists(name string) bool {
+	key := strings.ToLower(name)
+	if len(p.functions[key]) > 0 || p.guarded[key] {
+		return true
+	}
+	_, ok := p.natives.GetBuiltin(name)
+	return ok
+}
+
+// classExists reports whether a class is declared, in the project or
+// built in, or tested for
+func (p *Project) classExists(name string) bool {
+	key := strings.ToLower(name)
+	return len(p.classes[key]) > 0 || p.guarded[key] || p.natives.HasClass(name)
+}
+
+// ============================================================================
+// Reporting
+// ============================================================================
+
+// report adds a warning of a rule about a node, if the rule runs
+func (c *checker) report(rule Rule, node ast.Node, message string) {
+	if !c.opts.runs(rule) {
+		return
+	}
+	start, end := node.Pos(), node.End()
+	c.diagnostics = append(c.diagnostics, diagnostic.Diagnostic{
+		Code:     string(rule),
+		Severity: diagnostic.SeverityWarning,
+		Message:  message,
+		File:     c.file,
+		Range: &diagnostic.Range{
+			Start: diagnostic.Position{Line: start.Line, Column: start.Column},
+			End:   diagnostic.Position{Line: end.Line, Column: end.Column},
+		},
+	})
+}
@@ -0,0 +1,338 @@
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/diagnostic"
+)
+
+// lint analyzes files, named a.php, b.php and so on, returning their
+// findings as "file:line: code: message"
+func lint(t *testing.T, opts Options, sources ...string) []string {
+	t.Helper()
+	p := NewProject()
+	for i, src := range sources {
+		p.AddFile(fmt.Sprintf("%c.php", 'a'+i), []byte(src))
+	}
+	var findings []string
+	for _, d := range p.Lint(opts) {
+		line := 0
+		if d.Range != nil {
+			line = d.Range.Start.Line
+		}
+		findings = append(findings, fmt.Sprintf("%s:%d: %s: %s", d.File, line, d.Code, d.Message))
+	}
+	return findings
+}
+
+func TestLint_Rules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     Rule
+		input    string
+		expected []string
+	}{
+		{
+			"undefined variables",
+			RuleUndefinedVariable,
+			`<?php
+function f($a) {
+    echo $a, $b;
+    if ($a) {
+        $c = 1;
+    }
+    echo $c;
+    if ($a) {
+        $d = 1;
+        return;
+    }
+    echo $d;
+}`,
+			[]string{"a.php:3: undefined-variable: Undefined variable $b", "a.php:12: undefined-variable: Undefined variable $d"},
+		},
+		{
+			"assignments",
+			RuleUndefinedVariable,
+			`<?php
+function f(array $xs) {
+    foreach ($xs as $k => [$v, $w]) {
+        echo $k, $v, $w;
+    }
+    ['a' => $a, 'x' => $b] = $xs;
+    $c = [$a];
+    $d ??= $b;
+    preg_match('/x/', 'x', $matches);
+    static $e;
+    global $g;
+    try {
+        $t = g();
+    } catch (Exception $ex) {
+        echo $t, $ex;
+    }
+    $h = fn ($y) => $y + $a + $z;
+    $i = function () use ($c, &$j) {
+        return $c + $j;
+    };
+    echo isset($u), $x ?? 1, $matches, $e, $g, $j;
+    unset($a);
+    echo $a;
+}`,
+			[]string{"a.php:17: undefined-variable: Undefined variable $z", "a.php:23: undefined-variable: Undefined variable $a"},
+		},
+		{
+			"loops",
+			RuleUndefinedVariable,
+			`<?php
+function f() {
+    for ($i = 0; $i < 3; $i++) {
+        if ($i > 0) {
+            echo $previous;
+        }
+        $previous = $i;
+    }
+    while (true) {
+        if (g()) {
+            $found = 1;
+            break;
+        }
+    }
+    echo $found;
+    while (g()) {
+        echo $late;
+    }
+    $late = 1;
+}`,
+			[]string{"a.php:17: undefined-variable: Undefined variable $late"},
+		},
+		{
+			"dynamic scopes",
+			RuleUndefinedVariable,
+			`<?php
+function f(array $vars) {
+    extract($vars);
+    echo $anything;
+}
+function g() {
+    include 'vars.php';
+    echo $included;
+}
+function h() {
+    echo $missing;
+}`,
+			[]string{"a.php:11: undefined-variable: Undefined variable $missing"},
+		},
+		{
+			"unreachable code",
+			RuleUnreachableCode,
+			`<?php
+function f($a) {
+    return 1;
+    echo 2;
+    echo 3;
+}
+function g($a) {
+    if ($a) {
+        throw new Exception();
+    } else {
+        exit(1);
+    }
+    echo 1;
+}
+function h() {
+    return 1;
+    function inner() {}
+}
+foreach ([1] as $x) {
+    continue;
+    echo $x;
+}`,
+			[]string{"a.php:4: unreachable-code: Unreachable code", "a.php:13: unreachable-code: Unreachable code", "a.php:21: unreachable-code: Unreachable code"},
+		},
+		{
+			"unused private members",
+			RuleUnusedPrivate,
+			`<?php
+class A {
+    private $used = 1;
+    private $unused;
+    private static $counter = 0;
+    public function __construct() {
+        $this->helper();
+        self::$counter = 1;
+        usort($this->used, [$this, 'compare']);
+    }
+    private function helper() { return $this->used; }
+    private function compare($a, $b) { return 0; }
+    private function recursive($n) { return $this->recursive($n - 1); }
+    private function __clone() {}
+}
+class B {
+    use T;
+    private function viaTrait() {}
+}`,
+			[]string{"a.php:4: unused-private: Private property A::$unused is never used", "a.php:13: unused-private: Private method A::recursive() is never used"},
+		},
+		{
+			"undefined functions and classes",
+			"",
+			`<?php
+namespace App;
+
+use Lib\Missing;
+
+function helper() {}
+
+helper();
+array_map(null, []);
+undefinedFunction();
+new Missing();
+new \Exception();
+new Model();
+Model::create();
+Model::class;
+if (function_exists('optional')) {
+    optional();
+}
+class Model extends Base implements \Stringable {
+    public static function create() { return new static(); }
+    public function __toString(): string { return ''; }
+}`,
+			[]string{
+				"a.php:10: undefined-function: Call to undefined function App\\undefinedFunction()",
+				"a.php:11: undefined-class: Class \"Lib\\Missing\" not found",
+				"a.php:19: undefined-class: Class \"App\\Base\" not found",
+			},
+		},
+		{
+			"argument counts",
+			RuleArgumentCount,
+			`<?php
+function two($a, $b) {}
+function optional($a, $b = 1, ...$rest) {}
+two(1);
+two(1, 2, 3);
+two(b: 2, a: 1);
+two(...[1, 2]);
+optional();
+optional(1, 2, 3, 4);
+class P {
+    public function __construct(int $x) {}
+    public static function make($a) {}
+    private function run($a) {}
+    public function go() { $this->run(); }
+}
+class Q extends P {}
+new Q();
+Q::make(1, 2);`,
+			[]string{
+				"a.php:4: argument-count: Too few arguments to function two(), 1 passed and exactly 2 expected",
+				"a.php:5: argument-count: Too many arguments to function two(), 3 passed and at most 2 expected",
+				"a.php:8: argument-count: Too few arguments to function optional(), 0 passed and at least 1 expected",
+				"a.php:14: argument-count: Too few arguments to method P::run(), 0 passed and exactly 1 expected",
+				"a.php:17: argument-count: Too few arguments to method P::__construct(), 0 passed and exactly 1 expected",
+				"a.php:18: argument-count: Too many arguments to method P::make(), 2 passed and at most 1 expected",
+			},
+		},
+		{
+			"constant conditions",
+			RuleConstantCondition,
+			`<?php
+if (true) {}
+if (!0) {} elseif ([]) {}
+if (!1) {}
+if (1 > 2) {}
+if ('a' === 'a') {}
+while (true) {}
+$x = null ? 1 : 2;`,
+			[]string{
+				"a.php:2: constant-condition: Condition is always true",
+				"a.php:3: constant-condition: Condition is always true",
+				"a.php:3: constant-condition: Condition is always false",
+				"a.php:4: constant-condition: Condition is always false",
+				"a.php:5: constant-condition: Condition is always false",
+				"a.php:6: constant-condition: Condition is always true",
+				"a.php:8: constant-condition: Condition is always false",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Disable: []Rule{RuleUndefinedVariable}}
+			if tt.rule != "" {
+				opts = Options{Enable: []Rule{tt.rule}}
+			}
+			got := lint(t, opts, tt.input)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected\n%s\ngot\n%s", strings.Join(tt.expected, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestLint_Project(t *testing.T) {
+	// Declarations are known across files
+	got := lint(t, Options{},
+		"<?php\nnamespace Lib;\nfunction util(int $x) {}\nclass Base {}",
+		"<?php\nuse Lib\\Base;\nuse function Lib\\util;\nclass Child extends Base {}\nutil(1);\nutil();",
+		"<?php\n$x = ;",
+	)
+	expected := []string{
+		"b.php:6: argument-count: Too few arguments to function Lib\\util(), 0 passed and exactly 1 expected",
+		"c.php:2: syntax-error: syntax error, unexpected ';'",
+	}
+	if len(got) != 2 || got[0] != expected[0] || !strings.HasPrefix(got[1], "c.php:2: ") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestLint_Options(t *testing.T) {
+	src := "<?php\nfunction f() {\n    return $x;\n    echo 1;\n}"
+	tests := []struct {
+		opts     Options
+		expected int
+	}{
+		{Options{}, 2},
+		{Options{Enable: []Rule{RuleUnreachableCode}}, 1},
+		{Options{Disable: []Rule{RuleUnreachableCode}}, 1},
+		{Options{Enable: []Rule{RuleUnreachableCode}, Disable: []Rule{RuleUnreachableCode}}, 0},
+	}
+	for _, tt := range tests {
+		if got := lint(t, tt.opts, src); len(got) != tt.expected {
+			t.Errorf("With %+v expected %d findings, got %v", tt.opts, tt.expected, got)
+		}
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	finding := func(line int, message string) diagnostic.Diagnostic {
+		return diagnostic.Diagnostic{Code: "undefined-variable", Message: message, File: "a.php",
+			Range: &diagnostic.Range{Start: diagnostic.Position{Line: line}}}
+	}
+	accepted := []diagnostic.Diagnostic{finding(3, "Undefined variable $x"), finding(9, "Undefined variable $x")}
+
+	var buf bytes.Buffer
+	if err := NewBaseline(accepted).Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	baseline, err := ReadBaseline(&buf)
+	if err != nil {
+		t.Fatalf("ReadBaseline: %v", err)
+	}
+	expected := []BaselineEntry{{File: "a.php", Code: "undefined-variable", Message: "Undefined variable $x", Count: 2}}
+	if !reflect.DeepEqual(baseline.Entries, expected) {
+		t.Errorf("Expected entries %+v, got %+v", expected, baseline.Entries)
+	}
+
+	// Lines may move; a third occurrence and other findings are new
+	current := []diagnostic.Diagnostic{finding(4, "Undefined variable $x"), finding(5, "Undefined variable $y"),
+		finding(10, "Undefined variable $x"), finding(20, "Undefined variable $x")}
+	got := baseline.Filter(current)
+	if len(got) != 2 || got[0].Range.Start.Line != 5 || got[1].Range.Start.Line != 20 {
+		t.Errorf("Expected the findings of lines 5 and 20, got %v", got)
+	}
+}
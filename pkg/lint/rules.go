@@ -0,0 +1,595 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/compiler"
+	"github.com/krizos/php-go/pkg/diagnostic"
+)
+
+// checker runs the rules over a file
+type checker struct {
+	project     *Project
+	opts        Options
+	file        string
+	ns          *compiler.NamespaceContext
+	class       *class // Class whose body is checked, nil outside classes
+	diagnostics []diagnostic.Diagnostic
+}
+
+// statements checks the statements of a file or namespace, following the
+// namespace declarations and imports
+func (c *checker) statements(stmts []ast.Stmt) {
+	c.unreachable(stmts)
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.NamespaceStatement:
+			c.ns = compiler.NewNamespaceContext(s.Name)
+			if s.Body != nil {
+				c.statements(s.Body.Statements)
+				c.ns = compiler.NewNamespaceContext("")
+			}
+		case *ast.UseStatement:
+			for _, item := range s.Items {
+				c.ns.AddUse(item.Kind, item.Name, item.Alias)
+			}
+		default:
+			ast.Inspect(stmt, c.visit)
+		}
+	}
+}
+
+// visit checks a node, returning whether to check its children
+func (c *checker) visit(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.ClassDeclaration:
+		if n.Extends != nil {
+			c.classReference(n.Extends)
+		}
+		for _, iface := range n.Implements {
+			c.classReference(iface)
+		}
+		c.classBody(qualify(c.ns, n.Name.Value), n.Body, true)
+		return false
+	case *ast.EnumDeclaration:
+		for _, iface := range n.Implements {
+			c.classReference(iface)
+		}
+		c.classBody(qualify(c.ns, n.Name.Value), n.Body, true)
+		return false
+	case *ast.TraitDeclaration:
+		// self and $this are the classes using the trait
+		c.classBody("", n.Body, false)
+		return false
+	case *ast.InterfaceDeclaration:
+		for _, parent := range n.Extends {
+			c.classReference(parent)
+		}
+		return false
+	case *ast.TraitUse:
+		for _, trait := range n.Traits {
+			c.classReference(trait)
+		}
+	case *ast.FunctionDeclaration:
+		if c.opts.runs(RuleUndefinedVariable) {
+			c.functionScope(n.Parameters, n.Body)
+		}
+	case *ast.MethodDeclaration:
+		if n.Body != nil && c.opts.runs(RuleUndefinedVariable) {
+			c.functionScope(n.Parameters, n.Body)
+		}
+	case *ast.BlockStatement:
+		c.unreachable(n.Statements)
+	case *ast.SwitchStatement:
+		for _, cs := range n.Cases {
+			c.unreachable(cs.Body)
+		}
+	case *ast.IfStatement:
+		c.condition(n.Condition)
+		for _, elseIf := range n.ElseIfs {
+			c.condition(elseIf.Condition)
+		}
+	case *ast.WhileStatement:
+		if !isLiteral(n.Condition, true) {
+			c.condition(n.Condition)
+		}
+	case *ast.DoWhileStatement:
+		if !isLiteral(n.Condition, false) {
+			c.condition(n.Condition)
+		}
+	case *ast.ForStatement:
+		if len(n.Condition) > 0 && !isLiteral(n.Condition[len(n.Condition)-1], true) {
+			c.condition(n.Condition[len(n.Condition)-1])
+		}
+	case *ast.TernaryExpression:
+		c.condition(n.Condition)
+	case *ast.CallExpression:
+		c.call(n)
+	case *ast.NewExpression:
+		c.newExpression(n)
+	case *ast.StaticCallExpression:
+		c.staticCall(n)
+	case *ast.MethodCallExpression:
+		c.methodCall(n)
+	case *ast.StaticPropertyExpression:
+		if ident, ok := n.Class.(*ast.Identifier); ok {
+			if name, ok := n.Property.(*ast.Identifier); !ok || !strings.EqualFold(name.Value, "class") {
+				c.classReference(ident)
+			}
+		}
+	}
+	return true
+}
+
+// classBody checks the members of a class-like declaration. name is the
+// fully qualified name of a class or enum, "" for a trait.
+func (c *checker) classBody(name string, body []ast.Stmt, checkPrivate bool) {
+	outer := c.class
+	c.class = nil
+	if name != "" {
+		c.class = c.project.class(name)
+	}
+	for _, stmt := range body {
+		ast.Inspect(stmt, c.visit)
+	}
+	c.class = outer
+	if checkPrivate {
+		c.unusedPrivate(name, body)
+	}
+}
+
+// ============================================================================
+// Unreachable code
+// ============================================================================
+
+// unreachable reports the first statement of a list following one that
+// never completes. Declarations are hoisted, so they are not unreachable.
+func (c *checker) unreachable(stmts []ast.Stmt) {
+	for i, stmt := range stmts {
+		if !terminates(stmt) {
+			continue
+		}
+		for _, next := range stmts[i+1:] {
+			switch next.(type) {
+			case *ast.FunctionDeclaration, *ast.ClassDeclaration, *ast.InterfaceDeclaration,
+				*ast.TraitDeclaration, *ast.EnumDeclaration:
+				continue
+			}
+			c.report(RuleUnreachableCode, next, "Unreachable code")
+			break
+		}
+		return
+	}
+}
+
+// terminates reports whether a statement never completes normally
+func terminates(stmt ast.Stmt) bool {
+	switch s := stmt.(type) {
+	case *ast.ReturnStatement, *ast.ThrowStatement, *ast.BreakStatement, *ast.ContinueStatement:
+		return true
+	case *ast.ExpressionStatement:
+		_, exit := s.Expression.(*ast.ExitExpression)
+		return exit
+	case *ast.BlockStatement:
+		return blockTerminates(s)
+	case *ast.IfStatement:
+		if s.Alternative == nil || !blockTerminates(s.Consequence) || !blockTerminates(s.Alternative) {
+			return false
+		}
+		for _, elseIf := range s.ElseIfs {
+			if !blockTerminates(elseIf.Consequence) {
+				return false
+			}
+		}
+		return true
+	case *ast.TryStatement:
+		if s.Finally != nil && blockTerminates(s.Finally) {
+			return true
+		}
+		if !blockTerminates(s.Body) {
+			return false
+		}
+		for _, catch := range s.CatchClauses {
+			if !blockTerminates(catch.Body) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// blockTerminates reports whether a block has a statement that never
+// completes
+func blockTerminates(block *ast.BlockStatement) bool {
+	if block == nil {
+		return false
+	}
+	for _, stmt := range block.Statements {
+		if terminates(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================================================
+// Constant conditions
+// ============================================================================
+
+// condition reports a condition whose value is known without running it
+func (c *checker) condition(cond ast.Expr) {
+	if value, ok := constant(cond); ok {
+		c.report(RuleConstantCondition, cond, fmt.Sprintf("Condition is always %t", value))
+	}
+}
+
+// isLiteral reports whether an expression is the literal true (or 1) or
+// false (or 0), as while (true) and do ... while (false) are written
+func isLiteral(expr ast.Expr, value bool) bool {
+	switch e := expr.(type) {
+	case *ast.GroupedExpression:
+		return isLiteral(e.Expr, value)
+	case *ast.BooleanLiteral:
+		return e.Value == value
+	case *ast.IntegerLiteral:
+		return (e.Value != 0) == value
+	}
+	return false
+}
+
+// constant returns the truth value of an expression made of literals,
+// reporting whether it is known
+func constant(expr ast.Expr) (bool, bool) {
+	switch e := expr.(type) {
+	case *ast.GroupedExpression:
+		return constant(e.Expr)
+	case *ast.BooleanLiteral:
+		return e.Value, true
+	case *ast.IntegerLiteral:
+		return e.Value != 0, true
+	case *ast.FloatLiteral:
+		return e.Value != 0, true
+	case *ast.StringLiteral:
+		return e.Value != "" && e.Value != "0", true
+	case *ast.NullLiteral:
+		return false, true
+	case *ast.ArrayExpression:
+		for _, element := range e.Elements {
+			if _, spread := element.Value.(*ast.SpreadExpression); spread {
+				return false, false
+			}
+		}
+		return len(e.Elements) > 0, true
+	case *ast.PrefixExpression:
+		if e.Operator == "!" {
+			value, ok := constant(e.Right)
+			return !value, ok
+		}
+	case *ast.InfixExpression:
+		return constantInfix(e)
+	}
+	return false, false
+}
+
+// constantInfix returns the truth value of a logical operation or a
+// comparison of literals
+func constantInfix(e *ast.InfixExpression) (bool, bool) {
+	left, leftOK := constant(e.Left)
+	right, rightOK := constant(e.Right)
+	switch strings.ToLower(e.Operator) {
+	case "&&", "and":
+		if leftOK && !left || rightOK && !right {
+			return false, true
+		}
+		return true, leftOK && rightOK
+	case "||", "or":
+		if leftOK && left || rightOK && right {
+			return true, true
+		}
+		return false, leftOK && rightOK
+	case "xor":
+		return left != right, leftOK && rightOK
+	}
+
+	// Comparisons of literals of the same type
+	switch l := e.Left.(type) {
+	case *ast.IntegerLiteral:
+		if r, ok := e.Right.(*ast.IntegerLiteral); ok {
+			return compare(e.Operator, l.Value, r.Value)
+		}
+	case *ast.StringLiteral:
+		if r, ok := e.Right.(*ast.StringLiteral); ok {
+			switch e.Operator {
+			case "===":
+				return l.Value == r.Value, true
+			case "!==":
+				return l.Value != r.Value, true
+			}
+		}
+	case *ast.BooleanLiteral:
+		if r, ok := e.Right.(*ast.BooleanLiteral); ok {
+			switch e.Operator {
+			case "===", "==":
+				return l.Value == r.Value, true
+			case "!==", "!=":
+				return l.Value != r.Value, true
+			}
+		}
+	case *ast.NullLiteral:
+		if _, ok := e.Right.(*ast.NullLiteral); ok {
+			switch e.Operator {
+			case "===", "==":
+				return true, true
+			case "!==", "!=":
+				return false, true
+			}
+		}
+	}
+	return false, false
+}
+
+// compare compares two integers
+func compare(operator string, a, b int64) (bool, bool) {
+	switch operator {
+	case "==", "===":
+		return a == b, true
+	case "!=", "!==", "<>":
+		return a != b, true
+	case "<":
+		return a < b, true
+	case "<=":
+		return a <= b, true
+	case ">":
+		return a > b, true
+	case ">=":
+		return a >= b, true
+	}
+	return false, false
+}
+
+// ============================================================================
+// Unused private members
+// ============================================================================
+
+// unusedPrivate reports the private methods and properties a class body
+// never uses. Members used through a variable name, or by the methods of
+// a trait, cannot be told apart, so the check is skipped for them.
+func (c *checker) unusedPrivate(className string, body []ast.Stmt) {
+	var methods []*ast.MethodDeclaration
+	var properties []*ast.PropertyItem
+	for _, stmt := range body {
+		switch s := stmt.(type) {
+		case *ast.TraitUse:
+			return
+		case *ast.MethodDeclaration:
+			if s.Visibility == "private" && !strings.HasPrefix(s.Name.Value, "__") {
+				methods = append(methods, s)
+			}
+		case *ast.PropertyDeclaration:
+			if s.Visibility == "private" {
+				properties = append(properties, s.Properties...)
+			}
+		}
+	}
+	if len(methods) == 0 && len(properties) == 0 {
+		return
+	}
+
+	usedMethods, usedProperties := make(map[string]bool), make(map[string]bool)
+	dynamicMethods, dynamicProperties := false, false
+	for _, stmt := range body {
+		// A method calling itself does not use it
+		current := ""
+		if m, ok := stmt.(*ast.MethodDeclaration); ok {
+			current = strings.ToLower(m.Name.Value)
+		}
+		useMethod := func(name string) {
+			if name = strings.ToLower(name); name != current {
+				usedMethods[name] = true
+			}
+		}
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.MethodCallExpression:
+				if name, ok := n.Method.(*ast.Identifier); ok {
+					useMethod(name.Value)
+				} else {
+					dynamicMethods = true
+				}
+			case *ast.StaticCallExpression:
+				if name, ok := n.Method.(*ast.Identifier); ok {
+					useMethod(name.Value)
+				} else {
+					dynamicMethods = true
+				}
+			case *ast.PropertyExpression:
+				if name, ok := n.Property.(*ast.Identifier); ok {
+					usedProperties[name.Value] = true
+				} else {
+					dynamicProperties = true
+				}
+			case *ast.NullsafePropertyExpression:
+				if name, ok := n.Property.(*ast.Identifier); ok {
+					usedProperties[name.Value] = true
+				} else {
+					dynamicProperties = true
+				}
+			case *ast.StaticPropertyExpression:
+				if name, ok := n.Property.(*ast.Variable); ok {
+					usedProperties[variableName(name)] = true
+				}
+			case *ast.CallExpression:
+				if name, ok := n.Function.(*ast.Identifier); ok && strings.EqualFold(name.Value, "get_object_vars") {
+					dynamicProperties = true
+				}
+			case *ast.StringLiteral:
+				// Callables and property names given as strings
+				useMethod(n.Value)
+				usedProperties[n.Value] = true
+			}
+			return true
+		})
+	}
+
+	if !dynamicMethods {
+		for _, m := range methods {
+			if !usedMethods[strings.ToLower(m.Name.Value)] {
+				c.report(RuleUnusedPrivate, m.Name, fmt.Sprintf("Private method %s::%s() is never used", className, m.Name.Value))
+			}
+		}
+	}
+	if !dynamicProperties {
+		for _, prop := range properties {
+			if name := variableName(prop.Name); !usedProperties[name] {
+				c.report(RuleUnusedPrivate, prop.Name, fmt.Sprintf("Private property %s::$%s is never used", className, name))
+			}
+		}
+	}
+}
+
+// variableName returns the name of a variable without its $
+func variableName(v *ast.Variable) string {
+	return strings.TrimPrefix(v.Name, "$")
+}
+
+// ============================================================================
+// Functions, classes and their arguments
+// ============================================================================
+
+// call checks a call to a named function
+func (c *checker) call(n *ast.CallExpression) {
+	ident, ok := n.Function.(*ast.Identifier)
+	if !ok {
+		return
+	}
+	if fn := c.resolveFunction(ident.Value); fn != nil {
+		c.arguments(n, "function "+fn.name, fn.params, n.Arguments)
+		return
+	}
+	name, fallback := c.ns.ResolveFunctionName(ident.Value)
+	if c.project.functionExists(name) || fallback != "" && c.project.functionExists(fallback) {
+		return
+	}
+	c.report(RuleUndefinedFunction, ident, fmt.Sprintf("Call to undefined function %s()", name))
+}
+
+// resolveFunction returns the project function a call by name calls, or
+// nil if it is not one, or not declared once
+func (c *checker) resolveFunction(name string) *function {
+	qualified, fallback := c.ns.ResolveFunctionName(name)
+	if fns := c.project.functions[strings.ToLower(qualified)]; len(fns) > 0 || fallback == "" {
+		return c.project.function(qualified)
+	}
+	return c.project.function(fallback)
+}
+
+// classReference checks that a named class exists, returning the project
+// class if it is one. self, static and parent name the class checked.
+func (c *checker) classReference(ident *ast.Identifier) *class {
+	name := c.ns.ResolveClassName(ident.Value)
+	switch name {
+	case "self", "static":
+		return c.class
+	case "parent":
+		if c.class != nil && c.class.parent != "" {
+			return c.project.class(c.class.parent)
+		}
+		return nil
+	}
+	if !c.project.classExists(name) {
+		c.report(RuleUndefinedClass, ident, fmt.Sprintf("Class \"%s\" not found", name))
+		return nil
+	}
+	return c.project.class(name)
+}
+
+// newExpression checks the class instantiated and the arguments of its
+// constructor
+func (c *checker) newExpression(n *ast.NewExpression) {
+	ident, ok := n.Class.(*ast.Identifier)
+	if !ok {
+		return
+	}
+	cls := c.classReference(ident)
+	if cls == nil || strings.EqualFold(ident.Value, "static") {
+		return
+	}
+	if ctor, owner := c.project.method(cls, "__construct"); ctor != nil {
+		c.arguments(n, "method "+owner.name+"::__construct", ctor.Parameters, n.Arguments)
+	}
+}
+
+// staticCall checks the class and the arguments of a static call
+func (c *checker) staticCall(n *ast.StaticCallExpression) {
+	ident, ok := n.Class.(*ast.Identifier)
+	if !ok {
+		return
+	}
+	cls := c.classReference(ident)
+	method, ok := n.Method.(*ast.Identifier)
+	if cls == nil || !ok || strings.EqualFold(ident.Value, "static") {
+		return
+	}
+	if m, owner := c.project.method(cls, method.Value); m != nil {
+		c.arguments(n, "method "+owner.name+"::"+m.Name.Value, m.Parameters, n.Arguments)
+	}
+}
+
+// methodCall checks the arguments of a call of a method of $this
+func (c *checker) methodCall(n *ast.MethodCallExpression) {
+	object, ok := n.Object.(*ast.Variable)
+	method, named := n.Method.(*ast.Identifier)
+	if !ok || !named || variableName(object) != "this" || c.class == nil {
+		return
+	}
+	if m, owner := c.project.method(c.class, method.Value); m != nil && m.Visibility == "private" {
+		// Other methods may be overridden by the class of $this
+		c.arguments(n, "method "+owner.name+"::"+m.Name.Value, m.Parameters, n.Arguments)
+	}
+}
+
+// arguments checks the number of arguments of a call against the
+// parameters of the function called. Calls unpacking arguments are not
+// checked.
+func (c *checker) arguments(call ast.Node, callee string, params []*ast.Parameter, args []ast.Expr) {
+	positional, named := 0, make(map[string]bool)
+	for _, arg := range args {
+		switch a := arg.(type) {
+		case *ast.SpreadExpression, *ast.VariadicPlaceholder:
+			return
+		case *ast.NamedArgument:
+			named[a.Name] = true
+		default:
+			positional++
+		}
+	}
+
+	required, maximum := 0, len(params)
+	for i, param := range params {
+		switch {
+		case param.Variadic:
+			maximum = -1
+		case param.DefaultValue == nil:
+			required = i + 1
+		}
+	}
+	passed := positional + len(named)
+
+	for i := positional; i < required; i++ {
+		param := params[i]
+		if param.DefaultValue == nil && !param.Variadic && !named[variableName(param.Name)] {
+			expected := "exactly"
+			if required != maximum {
+				expected = "at least"
+			}
+			c.report(RuleArgumentCount, call, fmt.Sprintf("Too few arguments to %s(), %d passed and %s %d expected", callee, passed, expected, required))
+			return
+		}
+	}
+	if maximum >= 0 && positional > maximum {
+		c.report(RuleArgumentCount, call, fmt.Sprintf("Too many arguments to %s(), %d passed and at most %d expected", callee, passed, maximum))
+	}
+}
@@ -0,0 +1,679 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/compiler"
+)
+
+// ============================================================================
+// Undefined variables
+// ============================================================================
+
+// The analysis follows the paths through a function body, tracking the
+// variables that may be assigned at each point: those assigned on at least
+// one path reaching it. A variable read where no path assigned it is
+// undefined along all paths.
+
+// superglobals are the variables defined in every scope
+var superglobals = map[string]bool{
+	"this": true, "GLOBALS": true, "_SERVER": true, "_GET": true, "_POST": true, "_FILES": true,
+	"_COOKIE": true, "_SESSION": true, "_REQUEST": true, "_ENV": true, "argc": true, "argv": true,
+	"http_response_header": true,
+}
+
+// state is what is known at a point of a function body
+type state struct {
+	vars map[string]bool // Variables assigned on some path to the point
+	dead bool            // No path reaches the point
+}
+
+// merge joins the states of the paths reaching a point
+func merge(states ...state) state {
+	merged := state{vars: make(map[string]bool), dead: true}
+	for _, s := range states {
+		if s.dead {
+			continue
+		}
+		merged.dead = false
+		for name := range s.vars {
+			merged.vars[name] = true
+		}
+	}
+	return merged
+}
+
+// frame is a loop or switch, which break and continue leave
+type frame struct {
+	loop      bool
+	breaks    []state // States jumping past the end
+	continues []state // States jumping to the next iteration
+}
+
+// scope analyzes a function body, or the code of a file
+type scope struct {
+	c        *checker
+	state    state
+	frames   []*frame
+	traps    []*state // States a try body may throw from, innermost last
+	silent   int      // Nonzero while the body of a loop is analyzed the first time
+	dynamic  bool     // Variables may be assigned by name
+	reported map[string]bool
+}
+
+func newScope(c *checker, vars map[string]bool, silent int) *scope {
+	return &scope{c: c, state: state{vars: vars}, silent: silent, reported: make(map[string]bool)}
+}
+
+// save returns a copy of the current state
+func (s *scope) save() state {
+	return merge(s.state)
+}
+
+// restore makes a copy of a state the current one
+func (s *scope) restore(st state) {
+	s.state = merge(st)
+}
+
+// fileScope analyzes the code of a file outside of its functions
+func (c *checker) fileScope(stmts []ast.Stmt) {
+	s := newScope(c, make(map[string]bool), 0)
+	s.dynamic = isDynamic(&ast.Program{Statements: stmts})
+	s.statements(stmts)
+}
+
+// functionScope analyzes a function or method body
+func (c *checker) functionScope(params []*ast.Parameter, body *ast.BlockStatement) {
+	vars := make(map[string]bool)
+	for _, param := range params {
+		vars[variableName(param.Name)] = true
+	}
+	s := newScope(c, vars, 0)
+	s.dynamic = isDynamic(body)
+	s.statements(body.Statements)
+}
+
+// isDynamic reports whether the code of a scope may assign variables by
+// name: with variable variables, extract(), parse_str() or an included file
+func isDynamic(node ast.Node) bool {
+	dynamic := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionDeclaration, *ast.ClassDeclaration, *ast.ClosureExpression, *ast.ArrowFunctionExpression:
+			return false
+		case *ast.VariableVariable, *ast.IncludeExpression:
+			dynamic = true
+		case *ast.CallExpression:
+			if ident, ok := n.Function.(*ast.Identifier); ok {
+				switch strings.ToLower(strings.TrimPrefix(ident.Value, "\\")) {
+				case "extract", "parse_str", "mb_parse_str":
+					dynamic = true
+				}
+			}
+		}
+		return !dynamic
+	})
+	return dynamic
+}
+
+// read checks a read of a variable
+func (s *scope) read(v *ast.Variable) {
+	name := variableName(v)
+	if s.state.dead || s.silent > 0 || s.dynamic || s.state.vars[name] || superglobals[name] || s.reported[name] {
+		return
+	}
+	s.reported[name] = true
+	s.c.report(RuleUndefinedVariable, v, fmt.Sprintf("Undefined variable $%s", name))
+}
+
+// define records an assignment of a variable
+func (s *scope) define(v *ast.Variable) {
+	s.state.vars[variableName(v)] = true
+}
+
+// ----------------------------------------------------------------------------
+// Statements
+// ----------------------------------------------------------------------------
+
+func (s *scope) statements(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		s.statement(stmt)
+	}
+}
+
+func (s *scope) statement(stmt ast.Stmt) {
+	switch n := stmt.(type) {
+	case *ast.FunctionDeclaration, *ast.ClassDeclaration, *ast.InterfaceDeclaration,
+		*ast.TraitDeclaration, *ast.EnumDeclaration, *ast.ConstStatement:
+		// Declarations have scopes of their own
+	case *ast.NamespaceStatement:
+		s.c.ns = compiler.NewNamespaceContext(n.Name)
+		if n.Body != nil {
+			s.statements(n.Body.Statements)
+			s.c.ns = compiler.NewNamespaceContext("")
+		}
+	case *ast.UseStatement:
+		for _, item := range n.Items {
+			s.c.ns.AddUse(item.Kind, item.Name, item.Alias)
+		}
+	case *ast.BlockStatement:
+		s.statements(n.Statements)
+	case *ast.ExpressionStatement:
+		s.expr(n.Expression)
+	case *ast.EchoStatement:
+		for _, e := range n.Expressions {
+			s.expr(e)
+		}
+	case *ast.ReturnStatement:
+		s.expr(n.ReturnValue)
+		s.state.dead = true
+	case *ast.ThrowStatement:
+		s.expr(n.Expression)
+		s.state.dead = true
+	case *ast.BreakStatement:
+		s.jump(n.Depth, false)
+	case *ast.ContinueStatement:
+		s.jump(n.Depth, true)
+	case *ast.IfStatement:
+		s.ifStatement(n)
+	case *ast.WhileStatement:
+		var exit state
+		breaks := s.iterate(func(*frame) {
+			s.expr(n.Condition)
+			exit = s.save()
+			s.statements(n.Body.Statements)
+		})
+		if isLiteral(n.Condition, true) {
+			exit.dead = true
+		}
+		s.restore(merge(append(breaks, exit)...))
+	case *ast.DoWhileStatement:
+		var exit state
+		breaks := s.iterate(func(f *frame) {
+			s.statements(n.Body.Statements)
+			s.restore(merge(append(f.continues, s.state)...))
+			s.expr(n.Condition)
+			exit = s.save()
+		})
+		s.restore(merge(append(breaks, exit)...))
+	case *ast.ForStatement:
+		for _, e := range n.Init {
+			s.expr(e)
+		}
+		var exit state
+		breaks := s.iterate(func(f *frame) {
+			for _, e := range n.Condition {
+				s.expr(e)
+			}
+			exit = s.save()
+			s.statements(n.Body.Statements)
+			s.restore(merge(append(f.continues, s.state)...))
+			for _, e := range n.Increment {
+				s.expr(e)
+			}
+		})
+		if len(n.Condition) == 0 || isLiteral(n.Condition[len(n.Condition)-1], true) {
+			exit.dead = true
+		}
+		s.restore(merge(append(breaks, exit)...))
+	case *ast.ForeachStatement:
+		s.expr(n.Array)
+		var exit state
+		breaks := s.iterate(func(*frame) {
+			exit = s.save()
+			if n.Key != nil {
+				s.assign(n.Key)
+			}
+			s.assign(n.Value)
+			s.statements(n.Body.Statements)
+		})
+		s.restore(merge(append(breaks, exit)...))
+	case *ast.SwitchStatement:
+		s.switchStatement(n)
+	case *ast.TryStatement:
+		s.tryStatement(n)
+	case *ast.StaticVarStatement:
+		for _, v := range n.Vars {
+			s.expr(v.Value)
+			s.define(v.Name)
+		}
+	case *ast.GlobalStatement:
+		for _, v := range n.Vars {
+			s.define(v)
+		}
+	case *ast.UnsetStatement:
+		for _, e := range n.Variables {
+			if v, ok := e.(*ast.Variable); ok {
+				delete(s.state.vars, variableName(v))
+			} else {
+				s.quiet(e)
+			}
+		}
+	case *ast.DeclareStatement:
+		if n.Body != nil {
+			s.statements(n.Body.Statements)
+		}
+	default:
+		s.children(stmt)
+	}
+
+	// What a try body assigns is known where it may throw
+	if !s.state.dead {
+		for _, trap := range s.traps {
+			for name := range s.state.vars {
+				trap.vars[name] = true
+			}
+		}
+	}
+}
+
+// jump leaves the loop or switch break or continue targets
+func (s *scope) jump(depth ast.Expr, next bool) {
+	n := 1
+	if literal, ok := depth.(*ast.IntegerLiteral); ok && literal.Value > 1 {
+		n = int(literal.Value)
+	}
+	if n <= len(s.frames) {
+		f := s.frames[len(s.frames)-n]
+		// continue targets a switch as break does
+		if next && f.loop {
+			f.continues = append(f.continues, s.save())
+		} else {
+			f.breaks = append(f.breaks, s.save())
+		}
+	}
+	s.state.dead = true
+}
+
+// iterate analyzes the body of a loop twice: silently first, to learn
+// what an iteration assigns, then from the state where those assignments
+// may have happened. It returns the states breaking out of the loop.
+func (s *scope) iterate(body func(f *frame)) []state {
+	entry := s.save()
+	run := func() *frame {
+		f := &frame{loop: true}
+		s.frames = append(s.frames, f)
+		body(f)
+		s.frames = s.frames[:len(s.frames)-1]
+		return f
+	}
+
+	s.silent++
+	f := run()
+	s.silent--
+	head := merge(append(f.continues, entry, s.state)...)
+	head.dead = entry.dead
+
+	s.restore(head)
+	return run().breaks
+}
+
+func (s *scope) ifStatement(n *ast.IfStatement) {
+	s.expr(n.Condition)
+	var outs []state
+	branch := func(block *ast.BlockStatement) {
+		in := s.save()
+		s.statements(block.Statements)
+		outs = append(outs, s.state)
+		s.restore(in)
+	}
+	branch(n.Consequence)
+	for _, elseIf := range n.ElseIfs {
+		s.expr(elseIf.Condition)
+		branch(elseIf.Consequence)
+	}
+	if n.Alternative != nil {
+		s.statements(n.Alternative.Statements)
+	}
+	s.restore(merge(append(outs, s.state)...))
+}
+
+func (s *scope) switchStatement(n *ast.SwitchStatement) {
+	s.expr(n.Subject)
+	hasDefault := false
+	for _, cs := range n.Cases {
+		if cs.Value == nil {
+			hasDefault = true
+		}
+		s.expr(cs.Value)
+	}
+	entry := s.save()
+
+	f := &frame{}
+	s.frames = append(s.frames, f)
+	previous := state{dead: true}
+	for _, cs := range n.Cases {
+		// A case is entered by matching, or by falling through the previous
+		s.restore(merge(entry, previous))
+		s.statements(cs.Body)
+		previous = s.save()
+	}
+	s.frames = s.frames[:len(s.frames)-1]
+
+	exits := append(f.breaks, previous)
+	if !hasDefault {
+		exits = append(exits, entry)
+	}
+	s.restore(merge(exits...))
+}
+
+func (s *scope) tryStatement(n *ast.TryStatement) {
+	thrown := s.save()
+	s.traps = append(s.traps, &thrown)
+	s.statements(n.Body.Statements)
+	s.traps = s.traps[:len(s.traps)-1]
+
+	outs := []state{s.state}
+	for _, catch := range n.CatchClauses {
+		s.restore(thrown)
+		if catch.Variable != nil {
+			s.define(catch.Variable)
+		}
+		s.statements(catch.Body.Statements)
+		outs = append(outs, s.state)
+	}
+	after := merge(outs...)
+
+	if n.Finally != nil {
+		// The finally block also runs when the try body or a catch exits
+		s.restore(merge(after, thrown))
+		s.statements(n.Finally.Statements)
+		if !s.state.dead {
+			s.state.dead = after.dead
+		}
+		return
+	}
+	s.restore(after)
+}
+
+// ----------------------------------------------------------------------------
+// Expressions
+// ----------------------------------------------------------------------------
+
+// children analyzes the children of a node in order
+func (s *scope) children(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == node {
+			return true
+		}
+		if n != nil {
+			s.expr(n)
+		}
+		return false
+	})
+}
+
+// expr analyzes an expression, in evaluation order
+func (s *scope) expr(node ast.Node) {
+	switch n := node.(type) {
+	case nil:
+	case *ast.Variable:
+		s.read(n)
+	case *ast.Identifier:
+		// A name
+	case *ast.AssignmentExpression:
+		switch n.Operator {
+		case "=":
+			s.expr(n.Right)
+		case "??=":
+			s.quiet(n.Left)
+			s.expr(n.Right)
+		default:
+			s.expr(n.Left)
+			s.expr(n.Right)
+		}
+		s.assign(n.Left)
+	case *ast.PrefixExpression:
+		switch n.Operator {
+		case "++", "--", "++(postfix)", "--(postfix)":
+			s.expr(n.Right)
+			s.assign(n.Right)
+		case "@":
+			s.silent++
+			s.expr(n.Right)
+			s.silent--
+		default:
+			s.expr(n.Right)
+		}
+	case *ast.InfixExpression:
+		switch strings.ToLower(n.Operator) {
+		case "??":
+			s.quiet(n.Left)
+			s.branches(n.Right)
+		case "&&", "||", "and", "or":
+			s.expr(n.Left)
+			s.branches(n.Right)
+		default:
+			s.expr(n.Left)
+			s.expr(n.Right)
+		}
+	case *ast.TernaryExpression:
+		s.expr(n.Condition)
+		if n.Consequence == nil {
+			s.branches(n.Alternative)
+		} else {
+			s.branches(n.Consequence, n.Alternative)
+		}
+	case *ast.MatchExpression:
+		s.expr(n.Subject)
+		var bodies []ast.Expr
+		for _, arm := range n.Arms {
+			for _, cond := range arm.Conditions {
+				s.expr(cond)
+			}
+			bodies = append(bodies, arm.Body)
+		}
+		in := s.save()
+		var outs []state
+		for _, body := range bodies {
+			s.restore(in)
+			s.expr(body)
+			outs = append(outs, s.state)
+		}
+		s.restore(merge(outs...))
+	case *ast.IssetExpression:
+		for _, e := range n.Variables {
+			s.quiet(e)
+		}
+	case *ast.EmptyExpression:
+		s.quiet(n.Expr)
+	case *ast.ArrayExpression:
+		for _, element := range n.Elements {
+			s.expr(element.Key)
+			if v, ok := element.Value.(*ast.Variable); ok && element.ByRef {
+				s.define(v)
+			} else {
+				s.expr(element.Value)
+			}
+		}
+	case *ast.IndexExpression:
+		s.expr(n.Left)
+		s.expr(n.Index)
+	case *ast.PropertyExpression:
+		s.expr(n.Object)
+		s.member(n.Property)
+	case *ast.NullsafePropertyExpression:
+		s.expr(n.Object)
+		s.member(n.Property)
+	case *ast.StaticPropertyExpression:
+		s.member(n.Class)
+		if _, ok := n.Property.(*ast.Variable); !ok {
+			s.member(n.Property)
+		}
+	case *ast.CallExpression:
+		var params []*ast.Parameter
+		if ident, ok := n.Function.(*ast.Identifier); ok {
+			if fn := s.c.resolveFunction(ident.Value); fn != nil {
+				params = fn.params
+			}
+		} else {
+			s.expr(n.Function)
+		}
+		s.arguments(n.Arguments, params)
+	case *ast.MethodCallExpression:
+		s.expr(n.Object)
+		s.member(n.Method)
+		s.arguments(n.Arguments, nil)
+	case *ast.StaticCallExpression:
+		s.member(n.Class)
+		s.member(n.Method)
+		s.arguments(n.Arguments, nil)
+	case *ast.NewExpression:
+		s.member(n.Class)
+		s.arguments(n.Arguments, nil)
+	case *ast.InstanceofExpression:
+		s.expr(n.Left)
+		s.member(n.Right)
+	case *ast.ExitExpression:
+		s.expr(n.Status)
+		s.state.dead = true
+	case *ast.ClosureExpression:
+		vars := make(map[string]bool)
+		for _, param := range n.Parameters {
+			vars[variableName(param.Name)] = true
+		}
+		for _, use := range n.Use {
+			if use.ByRef {
+				s.define(use.Variable)
+			} else {
+				s.read(use.Variable)
+			}
+			vars[variableName(use.Variable)] = true
+		}
+		closure := newScope(s.c, vars, s.silent)
+		closure.dynamic = isDynamic(n.Body)
+		closure.statements(n.Body.Statements)
+	case *ast.ArrowFunctionExpression:
+		// Arrow functions capture the whole scope by value
+		vars := s.save().vars
+		for _, param := range n.Parameters {
+			vars[variableName(param.Name)] = true
+		}
+		arrow := newScope(s.c, vars, s.silent)
+		arrow.dynamic = s.dynamic
+		arrow.reported = s.reported
+		arrow.expr(n.Body)
+	case *ast.ClassDeclaration:
+		// An anonymous class has scopes of its own
+	default:
+		s.children(node)
+	}
+}
+
+// member analyzes the name of a class or member, unless it is written as
+// an identifier
+func (s *scope) member(node ast.Expr) {
+	if _, ok := node.(*ast.Identifier); !ok {
+		s.expr(node)
+	}
+}
+
+// branches analyzes expressions evaluated on alternative paths
+func (s *scope) branches(exprs ...ast.Expr) {
+	in := s.save()
+	outs := []state{}
+	if len(exprs) == 1 {
+		outs = append(outs, in) // The path skipping the expression
+	}
+	for _, e := range exprs {
+		s.restore(in)
+		s.expr(e)
+		outs = append(outs, s.state)
+	}
+	s.restore(merge(outs...))
+}
+
+// quiet analyzes an expression isset() or ?? tests: its variables may be
+// undefined, but not those its subscripts read
+func (s *scope) quiet(node ast.Expr) {
+	switch n := node.(type) {
+	case *ast.Variable:
+	case *ast.IndexExpression:
+		s.quiet(n.Left)
+		s.expr(n.Index)
+	case *ast.PropertyExpression:
+		s.quiet(n.Object)
+		s.member(n.Property)
+	case *ast.NullsafePropertyExpression:
+		s.quiet(n.Object)
+		s.member(n.Property)
+	case *ast.StaticPropertyExpression:
+		s.member(n.Class)
+	default:
+		s.expr(node)
+	}
+}
+
+// arguments analyzes the arguments of a call. A variable passed to a
+// parameter by reference may be assigned by the call: the parameters of
+// builtins and methods are not known, so a variable passed to one is taken
+// as assigned rather than read.
+func (s *scope) arguments(args []ast.Expr, params []*ast.Parameter) {
+	for i, arg := range args {
+		byRef := params == nil
+		if params != nil && i < len(params) {
+			byRef = params[i].ByRef
+		} else if params != nil && len(params) > 0 && params[len(params)-1].Variadic {
+			byRef = params[len(params)-1].ByRef
+		}
+		if named, ok := arg.(*ast.NamedArgument); ok {
+			arg = named.Value
+			byRef = params == nil
+			for _, param := range params {
+				if variableName(param.Name) == named.Name {
+					byRef = param.ByRef
+				}
+			}
+		}
+		if byRef && reference(arg) {
+			s.assign(arg)
+		} else {
+			s.expr(arg)
+		}
+	}
+}
+
+// reference reports whether an expression can be passed by reference
+func reference(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Variable:
+		return true
+	case *ast.IndexExpression:
+		return reference(e.Left)
+	}
+	return false
+}
+
+// assign analyzes the target of an assignment
+func (s *scope) assign(target ast.Expr) {
+	switch t := target.(type) {
+	case *ast.Variable:
+		s.define(t)
+	case *ast.IndexExpression:
+		// Assigning an element of an undefined variable creates the array
+		s.expr(t.Index)
+		if reference(t.Left) {
+			s.assign(t.Left)
+		} else {
+			s.expr(t.Left)
+		}
+	case *ast.ListExpression:
+		s.elements(t.Elements)
+	case *ast.ArrayExpression:
+		s.elements(t.Elements)
+	default:
+		s.expr(target)
+	}
+}
+
+// elements analyzes the targets of a destructuring assignment
+func (s *scope) elements(elements []ast.ArrayElement) {
+	for _, element := range elements {
+		s.expr(element.Key)
+		if element.Value != nil {
+			s.assign(element.Value)
+		}
+	}
+}
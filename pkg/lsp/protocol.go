@@ -0,0 +1,229 @@
+// Package lsp implements a Language Server Protocol server for PHP over
+// stdio, built on php-go's own lexer and parser: diagnostics as documents
+// change, go-to-definition and hover for the functions and classes of the
+// workspace, and document symbols.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// JSON-RPC
+// ============================================================================
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// request is a JSON-RPC request, or a notification when it has no ID
+type request struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// responseError is the error of a failed request
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// readMessage reads a message framed by a Content-Length header
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// writeMessage writes a message with its Content-Length header
+func writeMessage(w io.Writer, message interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+// ============================================================================
+// Protocol Types
+// ============================================================================
+
+// Position is a zero-based line and UTF-16 character offset
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a span of a document, End excluded
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range of a document
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// severityError is the severity of syntax errors
+const severityError = 1
+
+// Diagnostic is an error shown in the editor
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Code     string `json:"code,omitempty"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+// MarkupContent is documentation in markdown
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// Hover is the information shown for the symbol under the cursor
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+// SymbolKind is the kind of a document symbol
+type SymbolKind int
+
+// The symbol kinds of PHP declarations
+const (
+	KindNamespace  SymbolKind = 3
+	KindClass      SymbolKind = 5
+	KindMethod     SymbolKind = 6
+	KindProperty   SymbolKind = 7
+	KindEnum       SymbolKind = 10
+	KindInterface  SymbolKind = 11
+	KindFunction   SymbolKind = 12
+	KindConstant   SymbolKind = 14
+	KindEnumMember SymbolKind = 22
+)
+
+// DocumentSymbol is a declaration in the outline of a document
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           SymbolKind       `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+// ============================================================================
+// Conversions
+// ============================================================================
+
+// pathFromURI returns the file path of a file: URI
+func pathFromURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// uriFromPath returns the file: URI of a path
+func uriFromPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// lineText returns a zero-based line of a document, without its newline
+func lineText(text string, line int) string {
+	for ; line > 0; line-- {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			return ""
+		}
+		text = text[i+1:]
+	}
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	return strings.TrimSuffix(text, "\r")
+}
+
+// utf16Column converts a byte offset within a line to UTF-16 code units
+func utf16Column(line string, offset int) int {
+	column := 0
+	for i, r := range line {
+		if i >= offset {
+			break
+		}
+		column++
+		if r >= 0x10000 {
+			column++
+		}
+	}
+	if offset > len(line) {
+		column += offset - len(line)
+	}
+	return column
+}
+
+// byteOffset converts a UTF-16 column within a line to a byte offset
+func byteOffset(line string, column int) int {
+	for i, r := range line {
+		if column <= 0 {
+			return i
+		}
+		column--
+		if r >= 0x10000 {
+			column--
+		}
+	}
+	return len(line)
+}
+
+// rangeAt returns the range of a name starting at a one-based line and
+// byte column of a document
+func rangeAt(text string, line, column int, name string) Range {
+	if line < 1 {
+		return Range{}
+	}
+	content := lineText(text, line-1)
+	start := utf16Column(content, column-1)
+	end := utf16Column(content, column-1+len(name))
+	if end <= start {
+		end++
+	}
+	return Range{
+		Start: Position{Line: line - 1, Character: start},
+		End:   Position{Line: line - 1, Character: end},
+	}
+}
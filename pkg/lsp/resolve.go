@@ -0,0 +1,212 @@
+package lsp
+
+import (
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// Name Resolution
+// ============================================================================
+
+// nameReference is the name under the cursor and how it is used
+type nameReference struct {
+	name      string // Without a leading backslash
+	qualifier string // The class or object before -> or ::, "" for a plain name
+	member    bool   // After -> or ::
+	static    bool   // After ::
+	call      bool   // Followed by (
+	new       bool   // After new
+	rng       Range
+}
+
+// isNameByte reports whether a byte can be part of a PHP name
+func isNameByte(b byte) bool {
+	return b == '_' || b == '\\' || b >= 0x80 ||
+		b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// referenceAt finds the name at a position of a document
+func referenceAt(text string, pos Position) (nameReference, bool) {
+	line := lineText(text, pos.Line)
+	offset := byteOffset(line, pos.Character)
+	start, end := offset, offset
+	for start > 0 && isNameByte(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isNameByte(line[end]) {
+		end++
+	}
+	if start > 0 && line[start-1] == '$' {
+		start-- // A variable, or a static property after ::
+	}
+	if start == end {
+		return nameReference{}, false
+	}
+
+	ref := nameReference{
+		name: strings.TrimPrefix(line[start:end], `\`),
+		rng: Range{
+			Start: Position{Line: pos.Line, Character: utf16Column(line, start)},
+			End:   Position{Line: pos.Line, Character: utf16Column(line, end)},
+		},
+	}
+	before := strings.TrimRight(line[:start], " \t")
+	switch {
+	case strings.HasSuffix(before, "->"):
+		ref.member = true
+		ref.qualifier = trailingName(strings.TrimSuffix(strings.TrimSuffix(before, "->"), "?"))
+	case strings.HasSuffix(before, "::"):
+		ref.member, ref.static = true, true
+		ref.qualifier = trailingName(strings.TrimSuffix(before, "::"))
+	default:
+		ref.new = strings.HasSuffix(strings.ToLower(before), "new")
+	}
+	ref.call = strings.HasPrefix(strings.TrimLeft(line[end:], " \t"), "(")
+
+	if strings.HasPrefix(ref.name, "$") && !ref.static {
+		return nameReference{}, false // Variables are not declarations
+	}
+	return ref, true
+}
+
+// trailingName returns the name or variable at the end of a text
+func trailingName(text string) string {
+	start := len(text)
+	for start > 0 && (isNameByte(text[start-1]) || text[start-1] == '$') {
+		start--
+	}
+	return strings.TrimPrefix(text[start:], `\`)
+}
+
+// resolve returns the declarations of the name at a position of a file,
+// and the range of the name
+func (s *Server) resolve(path string, pos Position) ([]*Symbol, Range) {
+	f := s.files[path]
+	if f == nil {
+		return nil, Range{}
+	}
+	ref, ok := referenceAt(f.text, pos)
+	if !ok {
+		return nil, Range{}
+	}
+	if ref.member {
+		return s.resolveMember(f, ref, pos), ref.rng
+	}
+
+	var symbols []*Symbol
+	for _, symbol := range s.topLevel() {
+		if !matchesName(symbol, ref.name) {
+			continue
+		}
+		isClass := symbol.Kind != KindFunction && symbol.Kind != KindConstant
+		switch {
+		case ref.new && !isClass, ref.call && !ref.new && symbol.Kind != KindFunction:
+			continue
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols, ref.rng
+}
+
+// resolveMember returns the members a member access may refer to: those
+// of the named class and its parents, those of the class the cursor is in
+// for $this, self, static and parent, or those of any class otherwise
+func (s *Server) resolveMember(f *file, ref nameReference, pos Position) []*Symbol {
+	name := ref.name
+	if !ref.static && !ref.call {
+		name = "$" + name // $object->property
+	}
+
+	var classes []*Symbol
+	switch strings.ToLower(ref.qualifier) {
+	case "$this", "self", "static":
+		if class := enclosingClass(f, pos); class != nil {
+			classes = s.hierarchy(class)
+		}
+	case "parent":
+		if class := enclosingClass(f, pos); class != nil {
+			classes = s.hierarchy(class)[1:]
+		}
+	default:
+		if !strings.HasPrefix(ref.qualifier, "$") {
+			for _, class := range s.topLevel() {
+				if class.Kind != KindFunction && class.Kind != KindConstant && matchesName(class, ref.qualifier) {
+					classes = append(classes, s.hierarchy(class)...)
+				}
+			}
+		}
+	}
+	if len(classes) == 0 {
+		classes = s.topLevel()
+	}
+
+	var symbols []*Symbol
+	for _, class := range classes {
+		for _, member := range class.Members {
+			if member.Name == name || member.Kind == KindMethod && strings.EqualFold(member.Name, name) {
+				symbols = append(symbols, member)
+			}
+		}
+	}
+	return symbols
+}
+
+// hierarchy returns a class followed by its ancestors
+func (s *Server) hierarchy(class *Symbol) []*Symbol {
+	chain := []*Symbol{class}
+	for seen := map[*Symbol]bool{class: true}; len(class.Parents) > 0; {
+		var parent *Symbol
+		for _, symbol := range s.topLevel() {
+			if symbol.Kind == class.Kind && matchesName(symbol, class.Parents[0]) && !seen[symbol] {
+				parent = symbol
+				break
+			}
+		}
+		if parent == nil {
+			break
+		}
+		seen[parent] = true
+		chain = append(chain, parent)
+		class = parent
+	}
+	return chain
+}
+
+// enclosingClass returns the class-like of a file declared last before a
+// position: the one the position is in, unless it is after the class
+func enclosingClass(f *file, pos Position) *Symbol {
+	var enclosing *Symbol
+	for _, symbol := range f.symbols {
+		if symbol.Kind != KindFunction && symbol.Kind != KindConstant && symbol.Start.Line-1 <= pos.Line {
+			enclosing = symbol
+		}
+	}
+	return enclosing
+}
+
+// topLevel returns the top-level declarations of the indexed files, in the
+// order of their paths
+func (s *Server) topLevel() []*Symbol {
+	paths := make([]string, 0, len(s.files))
+	for path := range s.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var symbols []*Symbol
+	for _, path := range paths {
+		symbols = append(symbols, s.files[path].symbols...)
+	}
+	return symbols
+}
+
+// matchesName reports whether a name refers to a top-level declaration:
+// fully qualified, or by its short name. Constants are case-sensitive.
+func matchesName(symbol *Symbol, name string) bool {
+	name = strings.TrimPrefix(name, `\`)
+	if symbol.Kind == KindConstant {
+		return symbol.Qualified == name || symbol.Name == name
+	}
+	return strings.EqualFold(symbol.Qualified, name) || strings.EqualFold(symbol.Name, name)
+}
@@ -0,0 +1,322 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/diagnostic"
+	"github.com/krizos/php-go/pkg/lexer"
+	"github.com/krizos/php-go/pkg/parser"
+)
+
+// ============================================================================
+// Server
+// ============================================================================
+
+// ErrExitWithoutShutdown is returned by Serve when the client sends exit
+// without shutting the server down first
+var ErrExitWithoutShutdown = errors.New("lsp: exit without shutdown")
+
+// Server is a language server for the PHP files of a workspace. The files
+// are indexed when the client initializes the server; open documents
+// replace their file in the index as they change.
+type Server struct {
+	out      io.Writer
+	files    map[string]*file // Indexed files by path
+	open     map[string]bool  // Paths of the open documents
+	shutdown bool
+}
+
+// file is the text of an indexed file and its declarations
+type file struct {
+	path    string
+	text    string
+	symbols []*Symbol
+}
+
+// NewServer creates a server
+func NewServer() *Server {
+	return &Server{files: map[string]*file{}, open: map[string]bool{}}
+}
+
+// Serve answers the messages read from r, writing to w, until the client
+// sends exit or r ends
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.out = w
+	reader := bufio.NewReader(r)
+	for {
+		body, err := readMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.respond(json.RawMessage("null"), nil, &responseError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+
+		result, rpcErr := s.handle(req.Method, req.Params)
+		if req.ID == nil {
+			continue // Notifications get no response
+		}
+		if err := s.respond(req.ID, result, rpcErr); err != nil {
+			return err
+		}
+	}
+}
+
+// respond writes the response to a request
+func (s *Server) respond(id json.RawMessage, result interface{}, rpcErr *responseError) error {
+	response := map[string]interface{}{"jsonrpc": "2.0", "id": id}
+	if rpcErr != nil {
+		response["error"] = rpcErr
+	} else {
+		response["result"] = result
+	}
+	return writeMessage(s.out, response)
+}
+
+// notify sends a notification to the client
+func (s *Server) notify(method string, params interface{}) {
+	writeMessage(s.out, map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// textDocumentParams are the parameters of the requests about a position
+// in a document
+type textDocumentParams struct {
+	TextDocument struct {
+		URI     string `json:"uri"`
+		Text    string `json:"text"`
+		Version int    `json:"version"`
+	} `json:"textDocument"`
+	Position       Position `json:"position"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+// handle runs a request or notification
+func (s *Server) handle(method string, raw json.RawMessage) (interface{}, *responseError) {
+	if method == "initialize" {
+		return s.initialize(raw)
+	}
+	if method == "shutdown" {
+		s.shutdown = true
+		return nil, nil
+	}
+
+	var params textDocumentParams
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+	}
+	uri := params.TextDocument.URI
+	path := pathFromURI(uri)
+
+	switch method {
+	case "initialized", "textDocument/didSave", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	case "textDocument/didOpen":
+		s.open[path] = true
+		s.update(uri, params.TextDocument.Text, params.TextDocument.Version)
+		return nil, nil
+	case "textDocument/didChange":
+		// The server asks for full document sync: the last change is the text
+		if n := len(params.ContentChanges); n > 0 {
+			s.update(uri, params.ContentChanges[n-1].Text, params.TextDocument.Version)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		delete(s.open, path)
+		delete(s.files, path)
+		if source, err := os.ReadFile(path); err == nil {
+			s.index(path, string(source))
+		}
+		s.notify("textDocument/publishDiagnostics", map[string]interface{}{"uri": uri, "diagnostics": []Diagnostic{}})
+		return nil, nil
+	case "textDocument/definition":
+		locations := []Location{}
+		symbols, _ := s.resolve(path, params.Position)
+		for _, symbol := range symbols {
+			locations = append(locations, s.location(symbol))
+		}
+		return locations, nil
+	case "textDocument/hover":
+		symbols, wordRange := s.resolve(path, params.Position)
+		if len(symbols) == 0 {
+			return nil, nil
+		}
+		return &Hover{Contents: MarkupContent{Kind: "markdown", Value: hoverText(symbols[0])}, Range: &wordRange}, nil
+	case "textDocument/documentSymbol":
+		f := s.files[path]
+		if f == nil {
+			return []DocumentSymbol{}, nil
+		}
+		return documentSymbols(f.text, f.symbols), nil
+	}
+
+	if strings.HasPrefix(method, "$/") {
+		return nil, nil // Optional notifications may be ignored
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + method}
+}
+
+// initialize indexes the workspace folders and returns the capabilities of
+// the server
+func (s *Server) initialize(raw json.RawMessage) (interface{}, *responseError) {
+	var params struct {
+		RootURI          string `json:"rootUri"`
+		RootPath         string `json:"rootPath"`
+		WorkspaceFolders []struct {
+			URI string `json:"uri"`
+		} `json:"workspaceFolders"`
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+
+	var roots []string
+	for _, folder := range params.WorkspaceFolders {
+		roots = append(roots, pathFromURI(folder.URI))
+	}
+	switch {
+	case len(roots) > 0:
+	case params.RootURI != "":
+		roots = append(roots, pathFromURI(params.RootURI))
+	case params.RootPath != "":
+		roots = append(roots, params.RootPath)
+	}
+	for _, root := range roots {
+		s.indexWorkspace(root)
+	}
+
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":       1, // Full
+			"definitionProvider":     true,
+			"hoverProvider":          true,
+			"documentSymbolProvider": true,
+		},
+		"serverInfo": map[string]string{"name": "php-go"},
+	}, nil
+}
+
+// indexWorkspace indexes the .php files under a directory, skipping hidden
+// directories and node_modules
+func (s *Server) indexWorkspace(root string) {
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".php") && !s.open[path] {
+			if source, err := os.ReadFile(path); err == nil {
+				s.index(path, string(source))
+			}
+		}
+		return nil
+	})
+}
+
+// index parses a file, recording its declarations, and returns the parser
+func (s *Server) index(path, text string) *parser.Parser {
+	p := parser.New(lexer.New(text, path))
+	program := p.ParseProgram()
+	s.files[path] = &file{path: path, text: text, symbols: collectSymbols(program)}
+	return p
+}
+
+// update reindexes an open document and publishes its syntax errors
+func (s *Server) update(uri, text string, version int) {
+	p := s.index(pathFromURI(uri), text)
+
+	diagnostics := []Diagnostic{}
+	for _, d := range diagnostic.FromParser(p) {
+		line := lineText(text, d.Range.Start.Line-1)
+		diagnostics = append(diagnostics, Diagnostic{
+			Range: Range{
+				Start: Position{Line: d.Range.Start.Line - 1, Character: utf16Column(line, d.Range.Start.Column-1)},
+				End:   Position{Line: d.Range.End.Line - 1, Character: utf16Column(line, d.Range.End.Column-1)},
+			},
+			Severity: severityError,
+			Code:     d.Code,
+			Source:   "php-go",
+			Message:  d.Message,
+		})
+	}
+	s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+		"uri":         uri,
+		"version":     version,
+		"diagnostics": diagnostics,
+	})
+}
+
+// location returns the location of a symbol's name
+func (s *Server) location(symbol *Symbol) Location {
+	path := symbol.NamePos.Filename
+	text := ""
+	if f := s.files[path]; f != nil {
+		text = f.text
+	}
+	return Location{URI: uriFromPath(path), Range: rangeAt(text, symbol.NamePos.Line, symbol.NamePos.Column, symbol.Name)}
+}
+
+// hoverText renders a symbol's declaration and documentation in markdown
+func hoverText(symbol *Symbol) string {
+	text := "```php\n"
+	if symbol.Container != "" {
+		text += "// " + symbol.Container + "\n"
+	} else if symbol.Qualified != symbol.Name {
+		text += "// " + symbol.Qualified + "\n"
+	}
+	text += symbol.Signature + "\n```"
+	if symbol.Doc != "" {
+		text += "\n\n" + symbol.Doc
+	}
+	return text
+}
+
+// documentSymbols converts the declarations of a document to its outline
+func documentSymbols(text string, symbols []*Symbol) []DocumentSymbol {
+	outline := []DocumentSymbol{}
+	for _, symbol := range symbols {
+		selection := rangeAt(text, symbol.NamePos.Line, symbol.NamePos.Column, symbol.Name)
+		start := rangeAt(text, symbol.Start.Line, symbol.Start.Column, "")
+		item := DocumentSymbol{
+			Name:           symbol.Name,
+			Detail:         symbol.Signature,
+			Kind:           symbol.Kind,
+			Range:          Range{Start: start.Start, End: selection.End},
+			SelectionRange: selection,
+		}
+		if len(symbol.Members) > 0 {
+			item.Children = documentSymbols(text, symbol.Members)
+		}
+		outline = append(outline, item)
+	}
+	return outline
+}
@@ -0,0 +1,226 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// session runs a server over the given messages, the ID of each request
+// being its index, and returns the messages it wrote
+func session(t *testing.T, messages ...map[string]interface{}) []map[string]interface{} {
+	t.Helper()
+	var in bytes.Buffer
+	for i, message := range messages {
+		message["jsonrpc"] = "2.0"
+		if _, ok := message["id"]; ok {
+			message["id"] = i
+		}
+		if err := writeMessage(&in, message); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := NewServer().Serve(&in, &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	var written []map[string]interface{}
+	reader := bufio.NewReader(&out)
+	for {
+		body, err := readMessage(reader)
+		if err == io.EOF {
+			return written
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var message map[string]interface{}
+		if err := json.Unmarshal(body, &message); err != nil {
+			t.Fatal(err)
+		}
+		written = append(written, message)
+	}
+}
+
+// response returns the result of the response to request id
+func response(t *testing.T, written []map[string]interface{}, id int) interface{} {
+	t.Helper()
+	for _, message := range written {
+		if n, ok := message["id"].(float64); ok && int(n) == id {
+			if message["error"] != nil {
+				t.Fatalf("request %d failed: %v", id, message["error"])
+			}
+			return message["result"]
+		}
+	}
+	t.Fatalf("no response to request %d", id)
+	return nil
+}
+
+func call(method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"id": nil, "method": method, "params": params}
+}
+
+func notification(method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"method": method, "params": params}
+}
+
+func at(uri string, line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     map[string]interface{}{"line": line, "character": character},
+	}
+}
+
+const library = `<?php
+namespace App;
+
+/**
+ * Greets someone.
+ */
+function greet(string $name, int $times = 1): string {
+	return $name;
+}
+
+class Base {
+	public function hello() {}
+}
+
+class Greeter extends Base {
+	const PREFIX = 'Hi';
+	private ?string $last = null;
+
+	public static function make(): static {
+		return new static();
+	}
+}
+`
+
+const script = `<?php
+use App\Greeter;
+
+echo \App\greet('x');
+$g = Greeter::make();
+$g->hello();
+`
+
+func TestServer_Session(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "lib.php"), []byte(library), 0644); err != nil {
+		t.Fatal(err)
+	}
+	uri := uriFromPath(filepath.Join(root, "main.php"))
+	libURI := uriFromPath(filepath.Join(root, "lib.php"))
+
+	written := session(t,
+		call("initialize", map[string]interface{}{"rootUri": uriFromPath(root)}),
+		notification("initialized", map[string]interface{}{}),
+		notification("textDocument/didOpen", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": uri, "text": script, "version": 1},
+		}),
+		call("textDocument/definition", at(uri, 3, 12)),       // 3: greet
+		call("textDocument/hover", at(uri, 3, 12)),            // 4
+		call("textDocument/definition", at(uri, 4, 7)),        // 5: Greeter
+		call("textDocument/definition", at(uri, 4, 16)),       // 6: make
+		call("textDocument/definition", at(uri, 5, 6)),        // 7: hello
+		call("textDocument/documentSymbol", at(libURI, 0, 0)), // 8
+		notification("textDocument/didChange", map[string]interface{}{
+			"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+			"contentChanges": []interface{}{map[string]interface{}{"text": "<?php\n$a = (1;\n$b = 2 +;\n"}},
+		}),
+		call("shutdown", nil),
+		notification("exit", nil),
+	)
+
+	capabilities := response(t, written, 0).(map[string]interface{})["capabilities"].(map[string]interface{})
+	if capabilities["definitionProvider"] != true || capabilities["textDocumentSync"] != float64(1) {
+		t.Errorf("Unexpected capabilities %v", capabilities)
+	}
+
+	definition := func(id int) (string, float64) {
+		locations := response(t, written, id).([]interface{})
+		if len(locations) != 1 {
+			t.Fatalf("request %d: expected 1 location, got %v", id, locations)
+		}
+		location := locations[0].(map[string]interface{})
+		start := location["range"].(map[string]interface{})["start"].(map[string]interface{})
+		return location["uri"].(string), start["line"].(float64)
+	}
+	for id, line := range map[int]float64{3: 6, 5: 14, 6: 18, 7: 11} {
+		if gotURI, gotLine := definition(id); gotURI != libURI || gotLine != line {
+			t.Errorf("request %d: expected %s line %v, got %s line %v", id, libURI, line, gotURI, gotLine)
+		}
+	}
+
+	hover := response(t, written, 4).(map[string]interface{})["contents"].(map[string]interface{})["value"].(string)
+	if !strings.Contains(hover, "function greet(string $name, int $times = 1): string") || !strings.Contains(hover, "Greets someone.") {
+		t.Errorf("Unexpected hover %q", hover)
+	}
+
+	symbols := response(t, written, 8).([]interface{})
+	var outline []string
+	for _, symbol := range symbols {
+		symbol := symbol.(map[string]interface{})
+		outline = append(outline, symbol["name"].(string))
+		if children, ok := symbol["children"].([]interface{}); ok {
+			for _, child := range children {
+				outline = append(outline, "  "+child.(map[string]interface{})["name"].(string))
+			}
+		}
+	}
+	if got := strings.Join(outline, ","); got != "greet,Base,  hello,Greeter,  PREFIX,  $last,  make" {
+		t.Errorf("Unexpected outline %s", got)
+	}
+
+	// Diagnostics are published on open, then both errors of the change
+	var published [][]interface{}
+	for _, message := range written {
+		if message["method"] == "textDocument/publishDiagnostics" {
+			published = append(published, message["params"].(map[string]interface{})["diagnostics"].([]interface{}))
+		}
+	}
+	if len(published) != 2 || len(published[0]) != 0 || len(published[1]) != 2 {
+		t.Fatalf("Expected no diagnostics then 2, got %v", published)
+	}
+	first := published[1][0].(map[string]interface{})
+	start := first["range"].(map[string]interface{})["start"].(map[string]interface{})
+	if start["line"] != float64(1) || start["character"] != float64(7) || first["code"] != "expected-token" {
+		t.Errorf("Unexpected diagnostic %v", first)
+	}
+}
+
+func TestServer_ExitWithoutShutdown(t *testing.T) {
+	var in, out bytes.Buffer
+	writeMessage(&in, map[string]interface{}{"jsonrpc": "2.0", "method": "exit"})
+	if err := NewServer().Serve(&in, &out); err != ErrExitWithoutShutdown {
+		t.Errorf("Expected ErrExitWithoutShutdown, got %v", err)
+	}
+}
+
+func TestServer_UnknownMethod(t *testing.T) {
+	var in, out bytes.Buffer
+	writeMessage(&in, map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "workspace/unknown"})
+	NewServer().Serve(&in, &out)
+	if !strings.Contains(out.String(), `"code":-32601`) {
+		t.Errorf("Expected a method not found error, got %s", out.String())
+	}
+}
+
+func TestUTF16Columns(t *testing.T) {
+	line := "$é = '😀'; f();"
+	offset := strings.Index(line, "f(")
+	column := utf16Column(line, offset)
+	if column != 11 {
+		t.Errorf("Expected column 11, got %d", column)
+	}
+	if got := byteOffset(line, column); got != offset {
+		t.Errorf("Expected offset %d back, got %d", offset, got)
+	}
+}
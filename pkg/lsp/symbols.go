@@ -0,0 +1,311 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+// ============================================================================
+// Symbols
+// ============================================================================
+
+// Symbol is a declaration of a document: a function, class-like, constant
+// or class member
+type Symbol struct {
+	Name      string // As declared, with the $ of properties
+	Qualified string // Namespaced name of top-level symbols
+	Kind      SymbolKind
+	Signature string   // The declaration as PHP, without bodies
+	Doc       string   // The doc comment, without its delimiters
+	Container string   // Class-like declaring a member
+	Parents   []string // Classes or interfaces a class-like extends
+	Start     lexer.Position
+	NamePos   lexer.Position
+	Members   []*Symbol
+}
+
+// collectSymbols returns the declarations of a program, members nested in
+// their class-likes
+func collectSymbols(program *ast.Program) []*Symbol {
+	c := &collector{}
+	c.statements(program.Statements)
+	return c.symbols
+}
+
+// collector walks the statements of a program collecting declarations
+type collector struct {
+	namespace string
+	symbols   []*Symbol
+}
+
+// qualify prefixes a name with the current namespace
+func (c *collector) qualify(name string) string {
+	if c.namespace == "" {
+		return name
+	}
+	return c.namespace + `\` + name
+}
+
+func (c *collector) statements(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		c.statement(stmt)
+	}
+}
+
+func (c *collector) statement(stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case *ast.NamespaceStatement:
+		c.namespace = stmt.Name
+		if stmt.Body != nil {
+			c.statements(stmt.Body.Statements)
+			c.namespace = ""
+		}
+	case *ast.BlockStatement:
+		c.statements(stmt.Statements)
+	case *ast.FunctionDeclaration:
+		c.add(&Symbol{
+			Name:      stmt.Name.Value,
+			Kind:      KindFunction,
+			Signature: "function " + reference(stmt.ByRef) + stmt.Name.Value + signature(stmt.Parameters, stmt.ReturnType),
+			Doc:       cleanDocComment(stmt.DocComment),
+			Start:     stmt.Token.Pos,
+			NamePos:   stmt.Name.Token.Pos,
+		})
+	case *ast.ConstStatement:
+		for _, item := range stmt.Constants {
+			c.add(&Symbol{
+				Name:      item.Name.Value,
+				Kind:      KindConstant,
+				Signature: "const " + item.Name.Value + " = " + valueString(item.Value),
+				Start:     stmt.Token.Pos,
+				NamePos:   item.Name.Token.Pos,
+			})
+		}
+	case *ast.ClassDeclaration:
+		header := strings.Join(append(append([]string{}, stmt.Modifiers...), "class", stmt.Name.Value), " ")
+		if stmt.Extends != nil {
+			header += " extends " + stmt.Extends.Value
+		}
+		header += names(" implements ", stmt.Implements)
+		class := c.classLike(stmt.Name, KindClass, header, stmt.DocComment, stmt.Token.Pos, stmt.Body)
+		if stmt.Extends != nil {
+			class.Parents = []string{stmt.Extends.Value}
+		}
+	case *ast.InterfaceDeclaration:
+		constants := make([]ast.Stmt, len(stmt.Constants))
+		for i, constant := range stmt.Constants {
+			constants[i] = constant
+		}
+		class := c.classLike(stmt.Name, KindInterface, "interface "+stmt.Name.Value+names(" extends ", stmt.Extends),
+			stmt.DocComment, stmt.Token.Pos, constants)
+		for _, parent := range stmt.Extends {
+			class.Parents = append(class.Parents, parent.Value)
+		}
+		for _, method := range stmt.Body {
+			class.Members = append(class.Members, &Symbol{
+				Name:      method.Name.Value,
+				Kind:      KindMethod,
+				Signature: "public function " + reference(method.ByRef) + method.Name.Value + signature(method.Parameters, method.ReturnType),
+				Doc:       cleanDocComment(method.DocComment),
+				Container: class.Name,
+				Start:     method.Token.Pos,
+				NamePos:   method.Name.Token.Pos,
+			})
+		}
+	case *ast.TraitDeclaration:
+		c.classLike(stmt.Name, KindClass, "trait "+stmt.Name.Value, stmt.DocComment, stmt.Token.Pos, stmt.Body)
+	case *ast.EnumDeclaration:
+		header := "enum " + stmt.Name.Value
+		if stmt.BackingType != nil {
+			header += ": " + stmt.BackingType.Value
+		}
+		header += names(" implements ", stmt.Implements)
+		c.classLike(stmt.Name, KindEnum, header, stmt.DocComment, stmt.Token.Pos, stmt.Body)
+	}
+}
+
+// add records a top-level declaration
+func (c *collector) add(symbol *Symbol) {
+	symbol.Qualified = c.qualify(symbol.Name)
+	c.symbols = append(c.symbols, symbol)
+}
+
+// classLike records a class, interface, trait or enum with its members
+func (c *collector) classLike(name *ast.Identifier, kind SymbolKind, header, doc string, start lexer.Position, body []ast.Stmt) *Symbol {
+	class := &Symbol{
+		Name:      name.Value,
+		Kind:      kind,
+		Signature: header,
+		Doc:       cleanDocComment(doc),
+		Start:     start,
+		NamePos:   name.Token.Pos,
+	}
+	c.add(class)
+	for _, stmt := range body {
+		class.Members = append(class.Members, members(class.Name, stmt)...)
+	}
+	return class
+}
+
+// members returns the members a statement of a class body declares
+func members(container string, stmt ast.Stmt) []*Symbol {
+	var symbols []*Symbol
+	switch stmt := stmt.(type) {
+	case *ast.MethodDeclaration:
+		modifiers := visibility(stmt.Visibility)
+		if stmt.Abstract {
+			modifiers = "abstract " + modifiers
+		}
+		if stmt.Final {
+			modifiers = "final " + modifiers
+		}
+		if stmt.Static {
+			modifiers += " static"
+		}
+		symbols = append(symbols, &Symbol{
+			Name:      stmt.Name.Value,
+			Kind:      KindMethod,
+			Signature: modifiers + " function " + reference(stmt.ByRef) + stmt.Name.Value + signature(stmt.Parameters, stmt.ReturnType),
+			Doc:       cleanDocComment(stmt.DocComment),
+			Start:     stmt.Token.Pos,
+			NamePos:   stmt.Name.Token.Pos,
+		})
+	case *ast.PropertyDeclaration:
+		modifiers := visibility(stmt.Visibility)
+		if stmt.Static {
+			modifiers += " static"
+		}
+		if stmt.Readonly {
+			modifiers += " readonly"
+		}
+		if stmt.Type != nil {
+			modifiers += " " + stmt.Type.String()
+		}
+		for _, item := range stmt.Properties {
+			declaration := modifiers + " " + item.Name.String()
+			if item.DefaultValue != nil {
+				declaration += " = " + valueString(item.DefaultValue)
+			}
+			symbols = append(symbols, &Symbol{
+				Name:      item.Name.String(),
+				Kind:      KindProperty,
+				Signature: declaration,
+				Doc:       cleanDocComment(stmt.DocComment),
+				Start:     stmt.Token.Pos,
+				NamePos:   item.Name.Token.Pos,
+			})
+		}
+	case *ast.ClassConstantDeclaration:
+		for _, item := range stmt.Constants {
+			symbols = append(symbols, &Symbol{
+				Name:      item.Name.Value,
+				Kind:      KindConstant,
+				Signature: visibility(stmt.Visibility) + " const " + item.Name.Value + " = " + valueString(item.Value),
+				Doc:       cleanDocComment(stmt.DocComment),
+				Start:     stmt.Token.Pos,
+				NamePos:   item.Name.Token.Pos,
+			})
+		}
+	case *ast.EnumCaseDeclaration:
+		declaration := "case " + stmt.Name.Value
+		if stmt.Value != nil {
+			declaration += " = " + valueString(stmt.Value)
+		}
+		symbols = append(symbols, &Symbol{
+			Name:      stmt.Name.Value,
+			Kind:      KindEnumMember,
+			Signature: declaration,
+			Start:     stmt.Token.Pos,
+			NamePos:   stmt.Name.Token.Pos,
+		})
+	}
+	for _, symbol := range symbols {
+		symbol.Container = container
+	}
+	return symbols
+}
+
+// signature formats a parameter list and return type
+func signature(params []*ast.Parameter, returnType ast.Expr) string {
+	parts := make([]string, len(params))
+	for i, param := range params {
+		var sb strings.Builder
+		if param.Type != nil {
+			sb.WriteString(param.Type.String() + " ")
+		}
+		if param.ByRef {
+			sb.WriteString("&")
+		}
+		if param.Variadic {
+			sb.WriteString("...")
+		}
+		sb.WriteString(param.Name.String())
+		if param.DefaultValue != nil {
+			sb.WriteString(" = " + valueString(param.DefaultValue))
+		}
+		parts[i] = sb.String()
+	}
+	out := "(" + strings.Join(parts, ", ") + ")"
+	if returnType != nil {
+		out += ": " + returnType.String()
+	}
+	return out
+}
+
+// valueString formats a constant expression, quoting strings, whose
+// literal is their content
+func valueString(expr ast.Expr) string {
+	if literal, ok := expr.(*ast.StringLiteral); ok {
+		return "'" + strings.ReplaceAll(strings.ReplaceAll(literal.Value, `\`, `\\`), "'", `\'`) + "'"
+	}
+	if expr == nil {
+		return ""
+	}
+	return expr.String()
+}
+
+// visibility returns the visibility of a member, public by default
+func visibility(v string) string {
+	if v == "" {
+		return "public"
+	}
+	return v
+}
+
+// reference returns the & of a function returning by reference
+func reference(byRef bool) string {
+	if byRef {
+		return "&"
+	}
+	return ""
+}
+
+// names formats a keyword followed by a list of names, if any
+func names(keyword string, identifiers []*ast.Identifier) string {
+	if len(identifiers) == 0 {
+		return ""
+	}
+	list := make([]string, len(identifiers))
+	for i, identifier := range identifiers {
+		list[i] = identifier.Value
+	}
+	return keyword + strings.Join(list, ", ")
+}
+
+// cleanDocComment strips the delimiters and leading asterisks of a doc
+// comment
+func cleanDocComment(doc string) string {
+	if doc == "" {
+		return ""
+	}
+	doc = strings.TrimSuffix(strings.TrimPrefix(doc, "/**"), "*/")
+	lines := strings.Split(doc, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "*")
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
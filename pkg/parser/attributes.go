@@ -0,0 +1,170 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+// parseAttributes parses the attribute groups before a declaration:
+// #[Name, Name(args)] #[Other]
+// It leaves the current token on the first token after the last group.
+func (p *Parser) parseAttributes() []*ast.AttributeGroup {
+	var groups []*ast.AttributeGroup
+
+	for p.curTokenIs(lexer.ATTRIBUTE_START) {
+		group := &ast.AttributeGroup{Token: p.curToken}
+
+		for {
+			if !p.expectPeek(lexer.IDENT) {
+				return nil
+			}
+			attr := &ast.Attribute{
+				Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+			}
+			if p.peekTokenIs(lexer.LPAREN) {
+				p.nextToken() // move to '('
+				attr.Arguments = p.parseCallArguments()
+			}
+			group.Attributes = append(group.Attributes, attr)
+
+			if !p.peekTokenIs(lexer.COMMA) {
+				break
+			}
+			p.nextToken() // consume comma
+
+			// Allow trailing comma
+			if p.peekTokenIs(lexer.RBRACKET) {
+				break
+			}
+		}
+
+		if !p.expectPeek(lexer.RBRACKET) {
+			return nil
+		}
+		groups = append(groups, group)
+		p.nextToken() // move past ']'
+	}
+
+	return groups
+}
+
+// parseAttributedStatement parses a function, class, interface, trait or
+// enum declaration preceded by attributes
+func (p *Parser) parseAttributedStatement() ast.Stmt {
+	attrs := p.parseAttributes()
+	if attrs == nil {
+		return nil
+	}
+
+	// Closures are expressions, unlike function declarations
+	var stmt ast.Stmt
+	if p.curTokenIs(lexer.FN) || p.curTokenIs(lexer.STATIC) || (p.curTokenIs(lexer.FUNCTION) && p.peekTokenIs(lexer.LPAREN)) {
+		stmt = p.parseExpressionStatement()
+	} else {
+		stmt = p.parseStatement()
+	}
+
+	switch node := stmt.(type) {
+	case *ast.FunctionDeclaration:
+		if node != nil {
+			node.Attributes = attrs
+		}
+	case *ast.ClassDeclaration:
+		if node != nil {
+			node.Attributes = attrs
+		}
+	case *ast.InterfaceDeclaration:
+		if node != nil {
+			node.Attributes = attrs
+		}
+	case *ast.TraitDeclaration:
+		if node != nil {
+			node.Attributes = attrs
+		}
+	case *ast.EnumDeclaration:
+		if node != nil {
+			node.Attributes = attrs
+		}
+	case *ast.ExpressionStatement:
+		if node != nil && !setClosureAttributes(node.Expression, attrs) {
+			p.error("attributes are only allowed on declarations and closures")
+		}
+	case nil:
+	default:
+		p.error(fmt.Sprintf("attributes are not allowed on %s statements", stmt.TokenLiteral()))
+	}
+	return stmt
+}
+
+// parseAttributedMember parses a class member preceded by attributes
+func (p *Parser) parseAttributedMember(parseMember func() ast.Stmt) ast.Stmt {
+	attrs := p.parseAttributes()
+	if attrs == nil {
+		return nil
+	}
+
+	member := parseMember()
+	switch node := member.(type) {
+	case *ast.MethodDeclaration:
+		if node != nil {
+			node.Attributes = attrs
+		}
+	case *ast.PropertyDeclaration:
+		if node != nil {
+			node.Attributes = attrs
+		}
+	case *ast.ClassConstantDeclaration:
+		if node != nil {
+			node.Attributes = attrs
+		}
+	case *ast.EnumCaseDeclaration:
+		if node != nil {
+			node.Attributes = attrs
+		}
+	case nil:
+	default:
+		p.error("attributes are not allowed on trait uses")
+	}
+	return member
+}
+
+// parseAttributedExpression parses a closure or arrow function preceded by
+// attributes
+func (p *Parser) parseAttributedExpression() ast.Expr {
+	attrs := p.parseAttributes()
+	if attrs == nil {
+		return nil
+	}
+
+	prefix := p.prefixParseFns[p.curToken.Type]
+	if prefix == nil {
+		p.error(fmt.Sprintf("unexpected %s after attributes", p.curToken.Type))
+		return nil
+	}
+	expr := prefix()
+	if expr != nil && !setClosureAttributes(expr, attrs) {
+		p.error("attributes are only allowed on declarations and closures")
+	}
+	return expr
+}
+
+// setClosureAttributes attaches attributes to a closure or arrow function
+func setClosureAttributes(expr ast.Expr, attrs []*ast.AttributeGroup) bool {
+	switch node := expr.(type) {
+	case *ast.ClosureExpression:
+		if node == nil {
+			return false
+		}
+		node.Attributes = attrs
+	case *ast.ArrowFunctionExpression:
+		if node == nil {
+			return false
+		}
+		node.Attributes = attrs
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+// attributeStrings returns the string form of attribute groups
+func attributeStrings(groups []*ast.AttributeGroup) []string {
+	out := make([]string, len(groups))
+	for i, group := range groups {
+		out[i] = group.String()
+	}
+	return out
+}
+
+func TestAttributes(t *testing.T) {
+	input := `<?php
+#[Entity(table: "users"), Cache]
+#[\App\Deprecated]
+final class User {
+	#[Id, Column("id")]
+	private int $id;
+
+	#[Since("1.0")]
+	const VERSION = 1;
+
+	#[Route("/users", methods: ["GET"],)]
+	public function index(#[FromQuery] int $page, #[SensitiveParameter] $token) {}
+}
+
+#[Pure]
+function helper() {}
+
+enum Status {
+	#[Label("On")]
+	case Active;
+}
+
+interface Repository {
+	#[Query]
+	public function find($id);
+}
+
+$f = #[Memoize] fn($x) => $x;
+#[Listener] static function () {};
+`
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	class := program.Statements[0].(*ast.ClassDeclaration)
+	if len(class.Modifiers) != 1 || class.Modifiers[0] != "final" {
+		t.Errorf("expected final class. got=%v", class.Modifiers)
+	}
+
+	prop := class.Body[0].(*ast.PropertyDeclaration)
+	constant := class.Body[1].(*ast.ClassConstantDeclaration)
+	method := class.Body[2].(*ast.MethodDeclaration)
+	function := program.Statements[1].(*ast.FunctionDeclaration)
+	enumCase := program.Statements[2].(*ast.EnumDeclaration).Body[0].(*ast.EnumCaseDeclaration)
+	signature := program.Statements[3].(*ast.InterfaceDeclaration).Body[0]
+	arrow := program.Statements[4].(*ast.ExpressionStatement).Expression.(*ast.AssignmentExpression).Right.(*ast.ArrowFunctionExpression)
+	closure := program.Statements[5].(*ast.ExpressionStatement).Expression.(*ast.ClosureExpression)
+
+	tests := []struct {
+		name  string
+		attrs []*ast.AttributeGroup
+		want  []string
+	}{
+		{"class", class.Attributes, []string{`#[Entity(table: users), Cache]`, `#[\App\Deprecated]`}},
+		{"property", prop.Attributes, []string{`#[Id, Column(id)]`}},
+		{"constant", constant.Attributes, []string{`#[Since(1.0)]`}},
+		{"method", method.Attributes, []string{`#[Route(/users, methods: [array])]`}},
+		{"first parameter", method.Parameters[0].Attributes, []string{`#[FromQuery]`}},
+		{"second parameter", method.Parameters[1].Attributes, []string{`#[SensitiveParameter]`}},
+		{"function", function.Attributes, []string{`#[Pure]`}},
+		{"enum case", enumCase.Attributes, []string{`#[Label(On)]`}},
+		{"interface method", signature.Attributes, []string{`#[Query]`}},
+		{"arrow function", arrow.Attributes, []string{`#[Memoize]`}},
+		{"closure", closure.Attributes, []string{`#[Listener]`}},
+	}
+
+	for _, tt := range tests {
+		got := attributeStrings(tt.attrs)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: expected attributes %v. got=%v", tt.name, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: expected attributes %v. got=%v", tt.name, tt.want, got)
+				break
+			}
+		}
+	}
+
+	if !closure.Static {
+		t.Error("expected a static closure")
+	}
+}
+
+func TestAttributeErrors(t *testing.T) {
+	tests := []string{
+		`<?php #[A] echo 1;`,
+		`<?php class C { #[A] use T; }`,
+		`<?php #[A(] class C {}`,
+	}
+
+	for _, input := range tests {
+		p := New(lexer.New(input, "test.php"))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected a parse error", input)
+		}
+	}
+}
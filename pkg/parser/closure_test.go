@@ -66,16 +66,16 @@ func TestParseClosure_WithUseClause(t *testing.T) {
 		t.Fatalf("Expected 2 use variables, got %d", len(closure.Use))
 	}
 
-	if closure.Use[0].Variable.Name != "$y" {
-		t.Errorf("Expected $y, got %s", closure.Use[0].Variable.Name)
+	if closure.Use[0].Variable.Name != "y" {
+		t.Errorf("Expected y, got %s", closure.Use[0].Variable.Name)
 	}
 
 	if closure.Use[0].ByRef {
 		t.Error("Expected $y to be by value, not by reference")
 	}
 
-	if closure.Use[1].Variable.Name != "$z" {
-		t.Errorf("Expected $z, got %s", closure.Use[1].Variable.Name)
+	if closure.Use[1].Variable.Name != "z" {
+		t.Errorf("Expected z, got %s", closure.Use[1].Variable.Name)
 	}
 
 	if !closure.Use[1].ByRef {
@@ -11,7 +11,8 @@ import (
 // function [&]name(params): returnType { body }
 func (p *Parser) parseFunctionDeclaration() *ast.FunctionDeclaration {
 	funcDecl := &ast.FunctionDeclaration{
-		Token: p.curToken,
+		Token:      p.curToken,
+		DocComment: p.takeDocComment(),
 	}
 
 	// Check for reference return (&function)
@@ -92,6 +93,12 @@ func (p *Parser) parseFunctionParameters() []*ast.Parameter {
 func (p *Parser) parseParameter() *ast.Parameter {
 	param := &ast.Parameter{}
 
+	if p.curTokenIs(lexer.ATTRIBUTE_START) {
+		if param.Attributes = p.parseAttributes(); param.Attributes == nil {
+			return nil
+		}
+	}
+
 	// Check for variadic (...)
 	if p.curTokenIs(lexer.ELLIPSIS) {
 		param.Variadic = true
@@ -157,7 +164,7 @@ func (p *Parser) parseParameter() *ast.Parameter {
 
 // parseTypeHint parses a type hint (now uses comprehensive type parser from types.go)
 // Supports: scalar types, nullable (?Type), union (A|B), intersection (A&B)
-func (p *Parser) parseTypeHint() ast.Expr {
+func (p *Parser) parseTypeHint() ast.TypeNode {
 	return p.parseType()
 }
 
@@ -165,9 +172,10 @@ func (p *Parser) parseTypeHint() ast.Expr {
 // [abstract|final] class Name [extends Parent] [implements Interface1, Interface2] { body }
 func (p *Parser) parseClassDeclaration() *ast.ClassDeclaration {
 	classDecl := &ast.ClassDeclaration{
-		Token:     p.curToken,
-		Modifiers: []string{},
-		Body:      []ast.Stmt{},
+		Token:      p.curToken,
+		DocComment: p.takeDocComment(),
+		Modifiers:  []string{},
+		Body:       []ast.Stmt{},
 	}
 
 	// Expect class name
@@ -207,7 +215,9 @@ func (p *Parser) parseClassDeclaration() *ast.ClassDeclaration {
 
 	// Parse class members
 	for !p.curTokenIs(lexer.RBRACE) && !p.curTokenIs(lexer.EOF) {
+		start := p.curToken.Pos
 		member := p.parseClassMember()
+		p.setSpan(member, start)
 		if member != nil {
 			classDecl.Body = append(classDecl.Body, member)
 		}
@@ -219,6 +229,10 @@ func (p *Parser) parseClassDeclaration() *ast.ClassDeclaration {
 
 // parseClassMember parses a class member (property, method, constant, trait use)
 func (p *Parser) parseClassMember() ast.Stmt {
+	if p.curTokenIs(lexer.ATTRIBUTE_START) {
+		return p.parseAttributedMember(p.parseClassMember)
+	}
+
 	// Check for use statement (traits)
 	if p.curTokenIs(lexer.USE) {
 		return p.parseTraitUse()
@@ -286,6 +300,7 @@ endModifiers:
 func (p *Parser) parseMethodDeclaration(visibility string, modifiers []string) *ast.MethodDeclaration {
 	method := &ast.MethodDeclaration{
 		Token:      p.curToken,
+		DocComment: p.takeDocComment(),
 		Visibility: visibility,
 	}
 
@@ -353,6 +368,7 @@ func (p *Parser) parseMethodDeclaration(visibility string, modifiers []string) *
 func (p *Parser) parsePropertyDeclaration(visibility string, modifiers []string) *ast.PropertyDeclaration {
 	prop := &ast.PropertyDeclaration{
 		Token:      p.curToken,
+		DocComment: p.takeDocComment(),
 		Visibility: visibility,
 		Properties: []*ast.PropertyItem{},
 	}
@@ -418,8 +434,9 @@ func (p *Parser) parsePropertyDeclaration(visibility string, modifiers []string)
 // parseInterfaceDeclaration parses an interface declaration
 func (p *Parser) parseInterfaceDeclaration() *ast.InterfaceDeclaration {
 	interfaceDecl := &ast.InterfaceDeclaration{
-		Token: p.curToken,
-		Body:  []*ast.MethodSignature{},
+		Token:      p.curToken,
+		DocComment: p.takeDocComment(),
+		Body:       []*ast.MethodSignature{},
 	}
 
 	// Expect interface name
@@ -445,16 +462,31 @@ func (p *Parser) parseInterfaceDeclaration() *ast.InterfaceDeclaration {
 
 	p.nextToken() // move into body
 
-	// Parse method signatures
+	// Parse constants and method signatures
 	for !p.curTokenIs(lexer.RBRACE) && !p.curTokenIs(lexer.EOF) {
+		start := p.curToken.Pos
+		var attrs []*ast.AttributeGroup
+		if p.curTokenIs(lexer.ATTRIBUTE_START) {
+			attrs = p.parseAttributes()
+		}
+
 		// Skip visibility modifiers (public is implicit in interfaces)
 		if p.curTokenIs(lexer.PUBLIC) {
 			p.nextToken()
 		}
 
-		if p.curTokenIs(lexer.FUNCTION) {
+		switch {
+		case p.curTokenIs(lexer.CONST):
+			constant := p.parseClassConstant("public")
+			if constant != nil {
+				constant.Attributes = attrs
+				p.setSpan(constant, start)
+				interfaceDecl.Constants = append(interfaceDecl.Constants, constant)
+			}
+		case p.curTokenIs(lexer.FUNCTION):
 			signature := p.parseMethodSignature()
 			if signature != nil {
+				signature.Attributes = attrs
 				interfaceDecl.Body = append(interfaceDecl.Body, signature)
 			}
 		}
@@ -467,7 +499,8 @@ func (p *Parser) parseInterfaceDeclaration() *ast.InterfaceDeclaration {
 // parseMethodSignature parses a method signature (no body)
 func (p *Parser) parseMethodSignature() *ast.MethodSignature {
 	signature := &ast.MethodSignature{
-		Token: p.curToken,
+		Token:      p.curToken,
+		DocComment: p.takeDocComment(),
 	}
 
 	// Check for reference return
@@ -511,8 +544,9 @@ func (p *Parser) parseMethodSignature() *ast.MethodSignature {
 // parseTraitDeclaration parses a trait declaration
 func (p *Parser) parseTraitDeclaration() *ast.TraitDeclaration {
 	traitDecl := &ast.TraitDeclaration{
-		Token: p.curToken,
-		Body:  []ast.Stmt{},
+		Token:      p.curToken,
+		DocComment: p.takeDocComment(),
+		Body:       []ast.Stmt{},
 	}
 
 	// Expect trait name
@@ -534,7 +568,9 @@ func (p *Parser) parseTraitDeclaration() *ast.TraitDeclaration {
 
 	// Parse trait members (properties and methods)
 	for !p.curTokenIs(lexer.RBRACE) && !p.curTokenIs(lexer.EOF) {
+		start := p.curToken.Pos
 		member := p.parseTraitMember()
+		p.setSpan(member, start)
 		if member != nil {
 			traitDecl.Body = append(traitDecl.Body, member)
 		}
@@ -544,8 +580,114 @@ func (p *Parser) parseTraitDeclaration() *ast.TraitDeclaration {
 	return traitDecl
 }
 
+// parseEnumDeclaration parses an enum declaration
+// enum Name [: int|string] [implements Interface1, Interface2] { cases and members }
+func (p *Parser) parseEnumDeclaration() *ast.EnumDeclaration {
+	enumDecl := &ast.EnumDeclaration{
+		Token:      p.curToken,
+		DocComment: p.takeDocComment(),
+		Body:       []ast.Stmt{},
+	}
+
+	// Expect enum name
+	if !p.expectPeek(lexer.IDENT) {
+		return nil
+	}
+
+	enumDecl.Name = &ast.Identifier{
+		Token: p.curToken,
+		Value: p.curToken.Literal,
+	}
+
+	// Parse backing type
+	if p.peekTokenIs(lexer.COLON) {
+		p.nextToken() // consume ':'
+		p.nextToken() // move to type (int and string are type tokens)
+		enumDecl.BackingType = &ast.Identifier{
+			Token: p.curToken,
+			Value: p.curToken.Literal,
+		}
+	}
+
+	// Parse implements clause
+	if p.peekTokenIs(lexer.IMPLEMENTS) {
+		p.nextToken() // consume implements
+		enumDecl.Implements = p.parseInterfaceList()
+	}
+
+	// Parse enum body
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+
+	p.nextToken() // move into body
+
+	// Parse cases and class members
+	for !p.curTokenIs(lexer.RBRACE) && !p.curTokenIs(lexer.EOF) {
+		start := p.curToken.Pos
+		member := p.parseEnumMember()
+		p.setSpan(member, start)
+		if member != nil {
+			enumDecl.Body = append(enumDecl.Body, member)
+		}
+		p.nextToken()
+	}
+
+	return enumDecl
+}
+
+// parseEnumMember parses an enum case or class member
+func (p *Parser) parseEnumMember() ast.Stmt {
+	if p.curTokenIs(lexer.ATTRIBUTE_START) {
+		return p.parseAttributedMember(p.parseEnumMember)
+	}
+	if p.curTokenIs(lexer.CASE) {
+		if enumCase := p.parseEnumCase(); enumCase != nil {
+			return enumCase
+		}
+		return nil
+	}
+	return p.parseClassMember()
+}
+
+// parseEnumCase parses an enum case: case Name [= value];
+func (p *Parser) parseEnumCase() *ast.EnumCaseDeclaration {
+	enumCase := &ast.EnumCaseDeclaration{Token: p.curToken}
+
+	if !p.expectPeek(lexer.IDENT) {
+		return nil
+	}
+	enumCase.Name = &ast.Identifier{
+		Token: p.curToken,
+		Value: p.curToken.Literal,
+	}
+
+	if p.peekTokenIs(lexer.ASSIGN) {
+		p.nextToken() // consume '='
+		p.nextToken() // move to value
+		enumCase.Value = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeek(lexer.SEMICOLON) {
+		return nil
+	}
+	return enumCase
+}
+
 // parseTraitMember parses a trait member (property or method)
 func (p *Parser) parseTraitMember() ast.Stmt {
+	if p.curTokenIs(lexer.ATTRIBUTE_START) {
+		return p.parseAttributedMember(p.parseTraitMember)
+	}
+
+	// Traits may use other traits and declare constants
+	if p.curTokenIs(lexer.USE) {
+		return p.parseTraitUse()
+	}
+	if p.curTokenIs(lexer.CONST) {
+		return p.parseClassConstant("public")
+	}
+
 	// Collect modifiers
 	var modifiers []string
 	visibility := "public"
@@ -620,10 +762,12 @@ func (p *Parser) parseTraitUse() *ast.TraitUse {
 
 		// Parse adaptations (insteadof, as)
 		for !p.curTokenIs(lexer.RBRACE) && !p.curTokenIs(lexer.EOF) {
-			// Simplified trait adaptation parsing
-			// Full implementation would handle complex cases
-			// For now, just skip to semicolon
-			p.skipToStatementEnd()
+			adaptation := p.parseTraitAdaptation()
+			if adaptation == nil {
+				p.skipToStatementEnd()
+			} else {
+				traitUse.Adaptations = append(traitUse.Adaptations, adaptation)
+			}
 			p.nextToken()
 		}
 	} else {
@@ -636,20 +780,129 @@ func (p *Parser) parseTraitUse() *ast.TraitUse {
 	return traitUse
 }
 
+// parseTraitAdaptation parses one rule of a trait use block:
+// Trait::method insteadof Other; or [Trait::]method as [visibility] [alias];
+func (p *Parser) parseTraitAdaptation() ast.TraitAdaptation {
+	if !p.curTokenIs(lexer.IDENT) {
+		p.error("expected trait method reference")
+		return nil
+	}
+
+	var traitName *ast.Identifier
+	method := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if p.peekTokenIs(lexer.PAAMAYIM_NEKUDOTAYIM) {
+		traitName = method
+		p.nextToken() // consume ::
+		p.nextToken() // move to method name
+		if p.curToken.Literal == "" {
+			p.error("expected method name")
+			return nil
+		}
+		// Method names may be reserved words
+		method = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	switch {
+	case p.peekTokenIs(lexer.INSTEADOF):
+		if traitName == nil {
+			p.error("expected trait name before insteadof")
+			return nil
+		}
+		p.nextToken()
+		precedence := &ast.TraitPrecedence{
+			Token:      p.curToken,
+			TraitName:  traitName,
+			MethodName: method,
+		}
+		for {
+			if !p.expectPeek(lexer.IDENT) {
+				return nil
+			}
+			precedence.Instead = append(precedence.Instead, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+			if !p.peekTokenIs(lexer.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(lexer.SEMICOLON) {
+			return nil
+		}
+		return precedence
+
+	case p.peekTokenIs(lexer.AS):
+		p.nextToken()
+		alias := &ast.TraitAlias{
+			Token:      p.curToken,
+			TraitName:  traitName,
+			MethodName: method,
+		}
+		switch p.peekToken.Type {
+		case lexer.PUBLIC, lexer.PROTECTED, lexer.PRIVATE:
+			p.nextToken()
+			alias.Visibility = p.curToken.Literal
+		}
+		if !p.peekTokenIs(lexer.SEMICOLON) {
+			p.nextToken()
+			if p.curToken.Literal == "" {
+				p.error("expected alias name")
+				return nil
+			}
+			alias.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		}
+		if alias.Alias == nil && alias.Visibility == "" {
+			p.error("expected visibility or alias name after as")
+			return nil
+		}
+		if !p.expectPeek(lexer.SEMICOLON) {
+			return nil
+		}
+		return alias
+	}
+
+	p.error("expected insteadof or as in trait adaptation")
+	return nil
+}
+
 // parseClassConstant parses a class constant declaration
 func (p *Parser) parseClassConstant(visibility string) *ast.ClassConstantDeclaration {
 	constDecl := &ast.ClassConstantDeclaration{
 		Token:      p.curToken,
+		DocComment: p.takeDocComment(),
 		Visibility: visibility,
-		Constants:  []*ast.ConstantItem{},
 	}
 
+	constants, ok := p.parseConstantItems()
+	if !ok {
+		return nil
+	}
+	constDecl.Constants = constants
+	return constDecl
+}
+
+// parseConstStatement parses a constant declaration outside classes:
+// const A = 1, B = 2;
+func (p *Parser) parseConstStatement() *ast.ConstStatement {
+	stmt := &ast.ConstStatement{Token: p.curToken}
+
+	constants, ok := p.parseConstantItems()
+	if !ok {
+		return nil
+	}
+	stmt.Constants = constants
+	return stmt
+}
+
+// parseConstantItems parses the NAME = value list following the const
+// keyword, and the terminating semicolon
+func (p *Parser) parseConstantItems() ([]*ast.ConstantItem, bool) {
+	constants := []*ast.ConstantItem{}
+
 	p.nextToken() // move to first constant name
 
 	for {
 		if !p.curTokenIs(lexer.IDENT) {
 			p.error("expected constant name")
-			return nil
+			return nil, false
 		}
 
 		constItem := &ast.ConstantItem{
@@ -661,13 +914,13 @@ func (p *Parser) parseClassConstant(visibility string) *ast.ClassConstantDeclara
 
 		// Expect = value
 		if !p.expectPeek(lexer.ASSIGN) {
-			return nil
+			return nil, false
 		}
 
 		p.nextToken() // move to value
 		constItem.Value = p.parseExpression(LOWEST)
 
-		constDecl.Constants = append(constDecl.Constants, constItem)
+		constants = append(constants, constItem)
 
 		if !p.peekTokenIs(lexer.COMMA) {
 			break
@@ -681,7 +934,7 @@ func (p *Parser) parseClassConstant(visibility string) *ast.ClassConstantDeclara
 		p.nextToken()
 	}
 
-	return constDecl
+	return constants, true
 }
 
 // parseInterfaceList parses a comma-separated list of interface names
@@ -273,6 +273,72 @@ final class Config {
 	}
 }
 
+func TestReadonlyClass(t *testing.T) {
+	input := `<?php
+final readonly class Money {
+	public function __construct() {}
+}`
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	classDecl := program.Statements[0].(*ast.ClassDeclaration)
+
+	if len(classDecl.Modifiers) != 2 || classDecl.Modifiers[0] != "final" || classDecl.Modifiers[1] != "readonly" {
+		t.Errorf("class should have 'final' and 'readonly' modifiers. got=%v", classDecl.Modifiers)
+	}
+}
+
+func TestEnumDeclaration(t *testing.T) {
+	input := `<?php
+enum Suit: string implements HasColor {
+	case Hearts = 'H';
+	case Spades = 'S';
+
+	const Wild = self::Spades;
+
+	public function color(): string {
+		return 'Red';
+	}
+}
+enum Status {
+	case Active;
+}`
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	backed := program.Statements[0].(*ast.EnumDeclaration)
+	if backed.Name.Value != "Suit" || backed.BackingType == nil || backed.BackingType.Value != "string" {
+		t.Fatalf("Expected enum Suit: string, got %s", backed.String())
+	}
+	if len(backed.Implements) != 1 || backed.Implements[0].Value != "HasColor" {
+		t.Errorf("Expected enum to implement HasColor, got %v", backed.Implements)
+	}
+	if len(backed.Body) != 4 {
+		t.Fatalf("Expected 4 members, got %d", len(backed.Body))
+	}
+	hearts, ok := backed.Body[0].(*ast.EnumCaseDeclaration)
+	if !ok || hearts.String() != "case Hearts = H;" {
+		t.Errorf("Expected case Hearts, got %s", backed.Body[0].String())
+	}
+	if _, ok := backed.Body[2].(*ast.ClassConstantDeclaration); !ok {
+		t.Errorf("Expected a constant, got %T", backed.Body[2])
+	}
+	if _, ok := backed.Body[3].(*ast.MethodDeclaration); !ok {
+		t.Errorf("Expected a method, got %T", backed.Body[3])
+	}
+
+	pure := program.Statements[1].(*ast.EnumDeclaration)
+	if pure.BackingType != nil || pure.Body[0].String() != "case Active;" {
+		t.Errorf("Expected pure enum Status, got %s", pure.String())
+	}
+}
+
 // Test class properties
 
 func TestPropertyDeclaration(t *testing.T) {
@@ -528,6 +594,33 @@ interface JsonRenderable extends Renderable, Serializable {
 	}
 }
 
+func TestInterfaceConstants(t *testing.T) {
+	input := `<?php
+interface HasVersion {
+	const VERSION = "1.0";
+	public function version(): string;
+	#[Deprecated]
+	public const OLD = 1, OLDER = 0;
+}`
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	interfaceDecl := program.Statements[0].(*ast.InterfaceDeclaration)
+
+	if len(interfaceDecl.Constants) != 2 || len(interfaceDecl.Body) != 1 {
+		t.Fatalf("expected 2 constant declarations and 1 method. got=%d, %d", len(interfaceDecl.Constants), len(interfaceDecl.Body))
+	}
+	if name := interfaceDecl.Constants[0].Constants[0].Name.Value; name != "VERSION" {
+		t.Errorf("constant name not 'VERSION'. got=%s", name)
+	}
+	if old := interfaceDecl.Constants[1]; len(old.Constants) != 2 || len(old.Attributes) != 1 {
+		t.Errorf("expected 2 constants with an attribute. got=%d, %d", len(old.Constants), len(old.Attributes))
+	}
+}
+
 // Test traits
 
 func TestTraitDeclaration(t *testing.T) {
@@ -604,6 +697,75 @@ class Post {
 	}
 }
 
+func TestTraitAdaptations(t *testing.T) {
+	input := `<?php
+class Talker {
+	use A, B {
+		B::smallTalk insteadof A;
+		A::bigTalk insteadof B, C;
+		B::bigTalk as talk;
+		smallTalk as protected;
+		A::list as private listAll;
+	}
+}`
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	classDecl := program.Statements[0].(*ast.ClassDeclaration)
+	traitUse := classDecl.Body[0].(*ast.TraitUse)
+
+	expected := []string{
+		"B::smallTalk insteadof A;",
+		"A::bigTalk insteadof B, C;",
+		"B::bigTalk as talk;",
+		"smallTalk as protected;",
+		"A::list as private listAll;",
+	}
+	if len(traitUse.Adaptations) != len(expected) {
+		t.Fatalf("expected %d adaptations. got=%d", len(expected), len(traitUse.Adaptations))
+	}
+	for i, want := range expected {
+		if got := traitUse.Adaptations[i].String(); got != want {
+			t.Errorf("adaptation %d: expected %q. got=%q", i, want, got)
+		}
+	}
+}
+
+func TestTraitMembers(t *testing.T) {
+	input := `<?php
+trait Counter {
+	use Resettable;
+	const START = 0;
+	abstract public function name(): string;
+	public static function create() { return new static(); }
+}`
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	traitDecl := program.Statements[0].(*ast.TraitDeclaration)
+	if len(traitDecl.Body) != 4 {
+		t.Fatalf("expected 4 members. got=%d", len(traitDecl.Body))
+	}
+	if _, ok := traitDecl.Body[0].(*ast.TraitUse); !ok {
+		t.Errorf("member 0 is not *ast.TraitUse. got=%T", traitDecl.Body[0])
+	}
+	if _, ok := traitDecl.Body[1].(*ast.ClassConstantDeclaration); !ok {
+		t.Errorf("member 1 is not *ast.ClassConstantDeclaration. got=%T", traitDecl.Body[1])
+	}
+	if method, ok := traitDecl.Body[2].(*ast.MethodDeclaration); !ok || !method.Abstract || method.Body != nil {
+		t.Errorf("member 2 is not an abstract method. got=%v", traitDecl.Body[2])
+	}
+	if method, ok := traitDecl.Body[3].(*ast.MethodDeclaration); !ok || !method.Static {
+		t.Errorf("member 3 is not a static method. got=%v", traitDecl.Body[3])
+	}
+}
+
 // Test class constants
 
 func TestClassConstant(t *testing.T) {
@@ -653,3 +815,66 @@ class Config {
 		t.Errorf("visibility not 'private'. got=%s", constDecl.Visibility)
 	}
 }
+
+func TestDocComments(t *testing.T) {
+	input := `<?php
+/** Function doc */
+function helper() {}
+
+/**
+ * Class doc
+ */
+#[Entity]
+final class User {
+	/** Constant doc */
+	const VERSION = 1;
+
+	/** Property doc */
+	private int $id;
+
+	/* Plain comment */
+	public $name;
+
+	/** Method doc */
+	public static function find($id) {}
+}
+
+/** Interface doc */
+interface Repository {
+	/** Signature doc */
+	public function all();
+}
+
+/** Statement doc */
+$x = 1;
+function undocumented() {}
+`
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	class := program.Statements[1].(*ast.ClassDeclaration)
+	iface := program.Statements[2].(*ast.InterfaceDeclaration)
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"function", program.Statements[0].(*ast.FunctionDeclaration).DocComment, "/** Function doc */"},
+		{"class", class.DocComment, "/**\n * Class doc\n */"},
+		{"constant", class.Body[0].(*ast.ClassConstantDeclaration).DocComment, "/** Constant doc */"},
+		{"property", class.Body[1].(*ast.PropertyDeclaration).DocComment, "/** Property doc */"},
+		{"undocumented property", class.Body[2].(*ast.PropertyDeclaration).DocComment, ""},
+		{"method", class.Body[3].(*ast.MethodDeclaration).DocComment, "/** Method doc */"},
+		{"interface", iface.DocComment, "/** Interface doc */"},
+		{"interface method", iface.Body[0].DocComment, "/** Signature doc */"},
+		{"function after a statement", program.Statements[4].(*ast.FunctionDeclaration).DocComment, ""},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: expected doc comment %q, got %q", tt.name, tt.want, tt.got)
+		}
+	}
+}
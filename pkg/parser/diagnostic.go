@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+// Diagnostic is a syntax error with the token it was found at, the tokens
+// that were expected there, and an excerpt of the source pointing at it
+type Diagnostic struct {
+	Pos      lexer.Position
+	Message  string
+	Token    lexer.Token       // The offending token
+	Expected []lexer.TokenType // Empty if the parser expected no token in particular
+	Excerpt  string            // The source line and a caret under the token
+}
+
+// String formats a diagnostic on one line, as Errors does
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("[%s] Parse error: %s", d.Pos, d.Message)
+}
+
+// Error implements the error interface
+func (d *Diagnostic) Error() string {
+	return d.String()
+}
+
+// Render formats a diagnostic with its source excerpt, for terminals
+func (d *Diagnostic) Render() string {
+	if d.Excerpt == "" {
+		return d.String()
+	}
+	return d.String() + "\n" + d.Excerpt
+}
+
+// excerpt renders the line of the source containing an offset, with a
+// caret line under the token starting there:
+//
+//	3 | echo $a +;
+//	  |          ^
+func excerpt(source string, pos lexer.Position, length int) string {
+	if pos.Offset < 0 || pos.Offset > len(source) {
+		return ""
+	}
+	start := strings.LastIndexByte(source[:pos.Offset], '\n') + 1
+	end := strings.IndexByte(source[pos.Offset:], '\n')
+	if end < 0 {
+		end = len(source)
+	} else {
+		end += pos.Offset
+	}
+	line := strings.TrimRight(source[start:end], "\r")
+
+	// The caret line keeps the tabs of the source so the caret lines up
+	var caret strings.Builder
+	for _, ch := range line[:min(pos.Offset-start, len(line))] {
+		if ch == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+	}
+	if column := pos.Offset - start; length > len(line)-column {
+		length = len(line) - column
+	}
+	caret.WriteString(strings.Repeat("^", max(length, 1)))
+
+	number := fmt.Sprintf("%d", pos.Line)
+	gutter := strings.Repeat(" ", len(number))
+	return fmt.Sprintf(" %s | %s\n %s | %s", number, line, gutter, caret.String())
+}
@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+func TestDiagnostics_RecoverAtStatementBoundaries(t *testing.T) {
+	input := `<?php
+$a = 1 +;
+echo "ok";
+function f() {
+	$b = (2;
+	return 3;
+}
+$c = ];
+`
+	p := New(lexer.New(input, "test.php"))
+	p.ParseProgram()
+
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) != 3 || len(p.Errors()) != 3 {
+		t.Fatalf("Expected 3 independent errors, got %d: %v", len(diagnostics), p.Errors())
+	}
+	for i, line := range []int{2, 5, 8} {
+		if got := diagnostics[i].Pos.Line; got != line {
+			t.Errorf("diagnostic %d: expected line %d, got %d (%s)", i, line, got, diagnostics[i])
+		}
+		if diagnostics[i].Pos.Filename != "test.php" {
+			t.Errorf("diagnostic %d: expected file test.php, got %q", i, diagnostics[i].Pos.Filename)
+		}
+	}
+
+	second := diagnostics[1]
+	if second.Token.Type != lexer.SEMICOLON || len(second.Expected) != 1 || second.Expected[0] != lexer.RPAREN {
+		t.Errorf("Expected ')' to be expected at ';', got token %s and expected %v", second.Token.Type, second.Expected)
+	}
+	want := " 5 | \t$b = (2;\n   | \t       ^"
+	if second.Excerpt != want {
+		t.Errorf("Expected excerpt\n%s\ngot\n%s", want, second.Excerpt)
+	}
+	if !strings.HasSuffix(second.Render(), want) || !strings.HasPrefix(second.Render(), second.String()) {
+		t.Errorf("Unexpected rendering:\n%s", second.Render())
+	}
+}
+
+func TestDiagnostics_NoCascade(t *testing.T) {
+	// One error in a statement is reported once, not once per token
+	p := New(lexer.New("<?php $a = ) ) ) ;\necho 1;", "test.php"))
+	program := p.ParseProgram()
+
+	if len(p.Diagnostics()) != 1 {
+		t.Fatalf("Expected 1 error, got %v", p.Errors())
+	}
+	if n := len(program.Statements); n == 0 {
+		t.Error("Expected parsing to resume at the next statement")
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	source := "<?php\r\necho 1\r\n"
+	got := excerpt(source, lexer.Position{Offset: 12, Line: 2}, 5)
+	if want := " 2 | echo 1\n   |      ^"; got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+	if got := excerpt(source, lexer.Position{Offset: 100}, 1); got != "" {
+		t.Errorf("Expected no excerpt out of the source, got %q", got)
+	}
+}
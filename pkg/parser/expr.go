@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/krizos/php-go/pkg/ast"
 	"github.com/krizos/php-go/pkg/lexer"
@@ -20,10 +21,12 @@ func (p *Parser) registerExpressionParsers() {
 	p.prefixParseFns = make(map[lexer.TokenType]prefixParseFn)
 	p.prefixParseFns[lexer.IDENT] = p.parseIdentifier
 	p.prefixParseFns[lexer.VARIABLE] = p.parseVariable
+	p.prefixParseFns[lexer.DOLLAR] = p.parseVariableVariable
 	p.prefixParseFns[lexer.INTEGER] = p.parseIntegerLiteral
 	p.prefixParseFns[lexer.FLOAT] = p.parseFloatLiteral
 	p.prefixParseFns[lexer.STRING] = p.parseStringLiteral
-	p.prefixParseFns[lexer.HEREDOC] = p.parseStringLiteral
+	p.prefixParseFns[lexer.ENCAPSED_START] = p.parseInterpolatedString
+	p.prefixParseFns[lexer.HEREDOC] = p.parseInterpolatedString
 	p.prefixParseFns[lexer.NOWDOC] = p.parseStringLiteral
 	p.prefixParseFns[lexer.TRUE] = p.parseBooleanLiteral
 	p.prefixParseFns[lexer.FALSE] = p.parseBooleanLiteral
@@ -41,7 +44,20 @@ func (p *Parser) registerExpressionParsers() {
 	p.prefixParseFns[lexer.MATCH] = p.parseMatchExpression
 	p.prefixParseFns[lexer.FUNCTION] = p.parseClosureExpression
 	p.prefixParseFns[lexer.FN] = p.parseArrowFunctionExpression
+	p.prefixParseFns[lexer.ATTRIBUTE_START] = p.parseAttributedExpression
 	p.prefixParseFns[lexer.STATIC] = p.parseStaticClosureOrProperty
+	p.prefixParseFns[lexer.INCLUDE] = p.parseIncludeExpression
+	p.prefixParseFns[lexer.INCLUDE_ONCE] = p.parseIncludeExpression
+	p.prefixParseFns[lexer.REQUIRE] = p.parseIncludeExpression
+	p.prefixParseFns[lexer.REQUIRE_ONCE] = p.parseIncludeExpression
+	p.prefixParseFns[lexer.EXIT] = p.parseExitExpression
+	p.prefixParseFns[lexer.ISSET] = p.parseIssetExpression
+	p.prefixParseFns[lexer.EMPTY] = p.parseEmptyExpression
+	p.prefixParseFns[lexer.LIST] = p.parseListExpression
+	for _, magic := range []lexer.TokenType{lexer.LINE_CONST, lexer.FILE_CONST, lexer.DIR_CONST, lexer.FUNCTION_CONST,
+		lexer.CLASS_CONST, lexer.TRAIT_CONST, lexer.METHOD_CONST, lexer.NAMESPACE_CONST} {
+		p.prefixParseFns[magic] = p.parseMagicConstant
+	}
 
 	// Infix parsers (operators that appear between expressions)
 	p.infixParseFns = make(map[lexer.TokenType]infixParseFn)
@@ -125,7 +141,9 @@ func (p *Parser) parseExpression(precedence int) ast.Expr {
 		return nil
 	}
 
+	start := p.curToken.Pos
 	leftExp := prefix()
+	p.setSpan(leftExp, start)
 
 	// Pratt parsing: continue parsing while the next operator has higher precedence
 	for !p.peekTokenIs(lexer.SEMICOLON) && precedence < p.peekTokenPrecedence() {
@@ -136,6 +154,7 @@ func (p *Parser) parseExpression(precedence int) ast.Expr {
 
 		p.nextToken()
 		leftExp = infix(leftExp)
+		p.setSpan(leftExp, start)
 	}
 
 	return leftExp
@@ -163,6 +182,33 @@ func (p *Parser) parseVariable() ast.Expr {
 	}
 }
 
+// parseVariableVariable parses $$name and ${expr}. The name binds to the
+// variable alone: $$a[0] is ($$a)[0].
+func (p *Parser) parseVariableVariable() ast.Expr {
+	vv := &ast.VariableVariable{Token: p.curToken}
+	p.nextToken()
+
+	switch {
+	case p.curTokenIs(lexer.LBRACE):
+		p.nextToken()
+		vv.Name = p.parseExpression(LOWEST)
+		if !p.expectPeek(lexer.RBRACE) {
+			return nil
+		}
+	case p.curTokenIs(lexer.VARIABLE):
+		vv.Name = p.parseVariable()
+	case p.curTokenIs(lexer.DOLLAR):
+		vv.Name = p.parseVariableVariable()
+	default:
+		p.error(fmt.Sprintf("expected variable name after $, got %s", p.curToken.Type))
+		return nil
+	}
+	if vv.Name == nil {
+		return nil
+	}
+	return vv
+}
+
 func (p *Parser) parseIntegerLiteral() ast.Expr {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
@@ -190,122 +236,87 @@ func (p *Parser) parseFloatLiteral() ast.Expr {
 }
 
 func (p *Parser) parseStringLiteral() ast.Expr {
-	strValue := p.curToken.Literal
-	token := p.curToken
-
-	// Check if string contains interpolation (simple $variable detection)
-	if !p.hasInterpolation(strValue) {
-		// No interpolation - return simple string literal
-		return &ast.StringLiteral{
-			Token: token,
-			Value: strValue,
-		}
+	return &ast.StringLiteral{
+		Token: p.curToken,
+		Value: p.curToken.Literal,
 	}
-
-	// String has interpolation - parse it into parts
-	return p.parseInterpolatedString(token, strValue)
 }
 
-// hasInterpolation checks if a string contains variable interpolation
-func (p *Parser) hasInterpolation(str string) bool {
-	for i := 0; i < len(str); i++ {
-		if str[i] == '\\' && i+1 < len(str) {
-			// Skip escaped characters
-			i++
-			continue
-		}
-		if str[i] == '$' && i+1 < len(str) {
-			// Check if next char is valid variable start (letter or underscore)
-			next := str[i+1]
-			if (next >= 'a' && next <= 'z') || (next >= 'A' && next <= 'Z') || next == '_' {
-				return true
+// parseInterpolatedString parses a double-quoted string or heredoc whose
+// raw body embeds variables
+// Example: "Hello $name" becomes ["Hello ", $name]
+func (p *Parser) parseInterpolatedString() ast.Expr {
+	token := p.curToken
+	quote, body := byte('"'), advance(token.Pos, `"`)
+	if token.Type == lexer.HEREDOC {
+		// The body starts on the line after <<<LABEL
+		quote, body = 0, token.Pos
+		input := p.l.Input()
+		if offset := token.Pos.Offset; offset < len(input) {
+			if i := strings.IndexByte(input[offset:], '\n'); i >= 0 {
+				body = advance(body, input[offset:offset+i+1])
 			}
 		}
 	}
-	return false
-}
 
-// parseInterpolatedString parses a string with interpolated variables
-// Example: "Hello $name" becomes ["Hello ", $name]
-func (p *Parser) parseInterpolatedString(token lexer.Token, str string) ast.Expr {
-	parts := []ast.Expr{}
-	var currentPart []byte
-	i := 0
-
-	for i < len(str) {
-		if str[i] == '\\' && i+1 < len(str) {
-			// Handle escape sequences
-			i++
-			switch str[i] {
-			case 'n':
-				currentPart = append(currentPart, '\n')
-			case 't':
-				currentPart = append(currentPart, '\t')
-			case 'r':
-				currentPart = append(currentPart, '\r')
-			case '\\':
-				currentPart = append(currentPart, '\\')
-			case '$':
-				currentPart = append(currentPart, '$')
-			default:
-				currentPart = append(currentPart, str[i])
-			}
-			i++
+	expression := &ast.InterpolatedStringExpression{Token: token}
+	for _, part := range lexer.SplitInterpolation(token.Literal, quote) {
+		if !part.IsVariable {
+			expression.Parts = append(expression.Parts, &ast.StringLiteral{
+				Token: token,
+				Value: part.Value,
+			})
 			continue
 		}
 
-		if str[i] == '$' && i+1 < len(str) {
-			next := str[i+1]
-			if (next >= 'a' && next <= 'z') || (next >= 'A' && next <= 'Z') || next == '_' {
-				// Found variable interpolation
-				// Add current string part if non-empty
-				if len(currentPart) > 0 {
-					parts = append(parts, &ast.StringLiteral{
-						Token: token,
-						Value: string(currentPart),
-					})
-					currentPart = nil
-				}
-
-				// Extract variable name
-				i++ // skip $
-				varStart := i
-				for i < len(str) && (isLetter(str[i]) || isDigit(str[i]) || str[i] == '_') {
-					i++
-				}
-				varName := str[varStart:i]
-
-				// Add variable to parts
-				parts = append(parts, &ast.Variable{
-					Token: token,
-					Name:  varName,
-				})
-				continue
-			}
+		// The source of {$expr} and ${expr} starts after the braces
+		prefix := token.Literal[:part.Offset]
+		switch rest := token.Literal[part.Offset:]; {
+		case strings.HasPrefix(rest, "{"):
+			prefix += "{"
+		case strings.HasPrefix(rest, "${"):
+			prefix += "${"
+		}
+		if expr := p.parseEmbeddedExpression(part.Value, advance(body, prefix)); expr != nil {
+			expression.Parts = append(expression.Parts, expr)
 		}
-
-		// Regular character
-		currentPart = append(currentPart, str[i])
-		i++
 	}
 
-	// Add final string part if non-empty
-	if len(currentPart) > 0 {
-		parts = append(parts, &ast.StringLiteral{
-			Token: token,
-			Value: string(currentPart),
-		})
+	// Strings without embedded expressions are plain literals
+	switch len(expression.Parts) {
+	case 0:
+		return &ast.StringLiteral{Token: token, Value: ""}
+	case 1:
+		if literal, ok := expression.Parts[0].(*ast.StringLiteral); ok {
+			return literal
+		}
 	}
+	return expression
+}
 
-	// If only one part, return it directly
-	if len(parts) == 1 {
-		return parts[0]
+// advance returns the position after text starting at pos
+func advance(pos lexer.Position, text string) lexer.Position {
+	pos.Offset += len(text)
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		pos.Line += strings.Count(text, "\n")
+		pos.Column = len(text) - i
+	} else {
+		pos.Column += len(text)
 	}
+	return pos
+}
 
-	return &ast.InterpolatedStringExpression{
-		Token: token,
-		Parts: parts,
+// parseEmbeddedExpression parses the source of an expression embedded in
+// a string, reporting its errors as errors of the enclosing parser
+func (p *Parser) parseEmbeddedExpression(source string, pos lexer.Position) ast.Expr {
+	embedded := New(lexer.NewAt(source, pos))
+	expr := embedded.parseExpression(LOWEST)
+	if !embedded.peekTokenIs(lexer.EOF) {
+		embedded.error(fmt.Sprintf("unexpected %s in interpolated string", embedded.peekToken.Literal))
 	}
+	p.errors = append(p.errors, embedded.errors...)
+	p.diagnostics = append(p.diagnostics, embedded.diagnostics...)
+	return expr
 }
 
 func (p *Parser) parseBooleanLiteral() ast.Expr {
@@ -321,6 +332,12 @@ func (p *Parser) parseNullLiteral() ast.Expr {
 	}
 }
 
+// parseMagicConstant parses __LINE__, __CLASS__ and the other magic
+// constants, which the compiler resolves
+func (p *Parser) parseMagicConstant() ast.Expr {
+	return &ast.MagicConstant{Token: p.curToken}
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expr {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
@@ -334,6 +351,104 @@ func (p *Parser) parsePrefixExpression() ast.Expr {
 	return expression
 }
 
+// parseIncludeExpression parses include/require expressions.
+// The path extends over the rest of the expression: include 'a' . '.php'
+func (p *Parser) parseIncludeExpression() ast.Expr {
+	expression := &ast.IncludeExpression{
+		Token: p.curToken,
+		Kind:  strings.ToLower(p.curToken.Literal),
+	}
+
+	p.nextToken()
+
+	expression.Path = p.parseExpression(LOWEST)
+
+	return expression
+}
+
+// parseExitExpression parses exit and die, with or without parentheses:
+// exit, exit(), exit(1), die("message")
+func (p *Parser) parseExitExpression() ast.Expr {
+	expression := &ast.ExitExpression{Token: p.curToken}
+
+	if !p.peekTokenIs(lexer.LPAREN) {
+		return expression
+	}
+	p.nextToken()
+
+	if p.peekTokenIs(lexer.RPAREN) {
+		p.nextToken()
+		return expression
+	}
+	p.nextToken()
+
+	expression.Status = p.parseExpression(LOWEST)
+	if !p.expectPeek(lexer.RPAREN) {
+		return nil
+	}
+
+	return expression
+}
+
+// parseIssetExpression parses isset($a, $b['k'], ...)
+func (p *Parser) parseIssetExpression() ast.Expr {
+	expression := &ast.IssetExpression{Token: p.curToken}
+
+	vars, ok := p.parseVariableList()
+	if !ok {
+		return nil
+	}
+	expression.Variables = vars
+
+	return expression
+}
+
+// parseEmptyExpression parses empty(expr)
+func (p *Parser) parseEmptyExpression() ast.Expr {
+	expression := &ast.EmptyExpression{Token: p.curToken}
+
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+
+	expression.Expr = p.parseExpression(LOWEST)
+	if !p.expectPeek(lexer.RPAREN) {
+		return nil
+	}
+
+	return expression
+}
+
+// parseVariableList parses the parenthesized variables of isset() and
+// unset(): at least one, with an optional trailing comma
+func (p *Parser) parseVariableList() ([]ast.Expr, bool) {
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil, false
+	}
+
+	vars := []ast.Expr{}
+	for !p.peekTokenIs(lexer.RPAREN) {
+		p.nextToken()
+		vars = append(vars, p.parseExpression(LOWEST))
+
+		if !p.peekTokenIs(lexer.COMMA) {
+			break
+		}
+		p.nextToken() // consume comma
+	}
+
+	if !p.expectPeek(lexer.RPAREN) {
+		return nil, false
+	}
+	if len(vars) == 0 {
+		p.error("expected at least one variable")
+		return nil, false
+	}
+
+	return vars, true
+}
+
 func (p *Parser) parseGroupedOrCastExpression() ast.Expr {
 	// Look ahead to determine if this is a cast or grouped expression
 	// Cast: (int), (string), (bool), (float), (array), (object)
@@ -382,40 +497,79 @@ func (p *Parser) parseCastExpression() ast.Expr {
 
 func (p *Parser) parseArrayExpression() ast.Expr {
 	array := &ast.ArrayExpression{
-		Token:    p.curToken,
-		Elements: []ast.ArrayElement{},
+		Token: p.curToken,
 	}
 
-	if p.peekTokenIs(lexer.RBRACKET) {
-		p.nextToken()
-		return array
+	elements, ok := p.parseArrayElements(lexer.RBRACKET)
+	if !ok {
+		return nil
 	}
+	array.Elements = elements
 
-	p.nextToken()
+	return array
+}
 
-	// Parse first element
-	array.Elements = append(array.Elements, p.parseArrayElement())
+// parseListExpression parses list($a, $b), including nested and keyed
+// patterns
+func (p *Parser) parseListExpression() ast.Expr {
+	list := &ast.ListExpression{
+		Token: p.curToken,
+	}
 
-	for p.peekTokenIs(lexer.COMMA) {
-		p.nextToken() // consume comma
-		p.nextToken() // move to next element
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil
+	}
 
-		// Allow trailing comma
-		if p.curTokenIs(lexer.RBRACKET) {
-			break
+	elements, ok := p.parseArrayElements(lexer.RPAREN)
+	if !ok {
+		return nil
+	}
+	list.Elements = listElements(elements)
+
+	return list
+}
+
+// parseArrayElements parses comma-separated array elements up to the
+// closing token, allowing a trailing comma. Empty elements, as in
+// [, $b] = $arr, are kept with a nil value; only list assignments accept
+// them.
+func (p *Parser) parseArrayElements(end lexer.TokenType) ([]ast.ArrayElement, bool) {
+	elements := []ast.ArrayElement{}
+
+	for !p.peekTokenIs(end) {
+		if p.peekTokenIs(lexer.COMMA) {
+			p.nextToken()
+			elements = append(elements, ast.ArrayElement{})
+			continue
 		}
 
-		array.Elements = append(array.Elements, p.parseArrayElement())
+		p.nextToken()
+		elements = append(elements, p.parseArrayElement())
+
+		if !p.peekTokenIs(lexer.COMMA) {
+			break
+		}
+		p.nextToken() // consume comma
 	}
 
-	if !p.expectPeek(lexer.RBRACKET) {
-		return nil
+	if !p.expectPeek(end) {
+		return nil, false
 	}
 
-	return array
+	return elements, true
 }
 
 func (p *Parser) parseArrayElement() ast.ArrayElement {
+	element := ast.ArrayElement{}
+
+	// By-reference value without key (&$value)
+	if p.curTokenIs(lexer.BITWISE_AND) {
+		element.ByRef = true
+		p.nextToken()
+		element.Value = p.parseExpression(LOWEST)
+		return element
+	}
+
 	// Parse first expression
 	expr := p.parseExpression(LOWEST)
 
@@ -424,18 +578,39 @@ func (p *Parser) parseArrayElement() ast.ArrayElement {
 		p.nextToken() // consume =>
 		p.nextToken() // move to value
 
-		value := p.parseExpression(LOWEST)
-		return ast.ArrayElement{
-			Key:   expr,
-			Value: value,
+		if p.curTokenIs(lexer.BITWISE_AND) {
+			element.ByRef = true
+			p.nextToken()
 		}
+
+		element.Key = expr
+		element.Value = p.parseExpression(LOWEST)
+		return element
 	}
 
 	// Non-associative element
-	return ast.ArrayElement{
-		Key:   nil,
-		Value: expr,
+	element.Value = expr
+	return element
+}
+
+// listFromArray turns an array literal on the left of an assignment, or
+// used as foreach value, into the destructuring pattern it stands for
+func listFromArray(array *ast.ArrayExpression) *ast.ListExpression {
+	return &ast.ListExpression{
+		Token:    array.Token,
+		Elements: listElements(array.Elements),
+	}
+}
+
+// listElements converts the nested array literals of a list pattern into
+// patterns too
+func listElements(elements []ast.ArrayElement) []ast.ArrayElement {
+	for i, elem := range elements {
+		if nested, ok := elem.Value.(*ast.ArrayExpression); ok {
+			elements[i].Value = listFromArray(nested)
+		}
 	}
+	return elements
 }
 
 func (p *Parser) parseNewExpression() ast.Expr {
@@ -468,8 +643,8 @@ func (p *Parser) parseInfixExpression(left ast.Expr) ast.Expr {
 
 	precedence := p.currentTokenPrecedence()
 
-	// Power operator is right-associative
-	if p.curTokenIs(lexer.POWER) {
+	// Power and null coalescing operators are right-associative
+	if p.curTokenIs(lexer.POWER) || p.curTokenIs(lexer.COALESCE) {
 		precedence--
 	}
 
@@ -486,6 +661,11 @@ func (p *Parser) parseAssignmentExpression(left ast.Expr) ast.Expr {
 		Left:     left,
 	}
 
+	// [$a, $b] = ... destructures instead of building an array
+	if array, ok := left.(*ast.ArrayExpression); ok && expression.Operator == "=" {
+		expression.Left = listFromArray(array)
+	}
+
 	p.nextToken()
 	expression.Right = p.parseExpression(ASSIGNMENT - 1) // Right-associative
 
@@ -542,7 +722,7 @@ func (p *Parser) parsePropertyOrMethodCall(left ast.Expr) ast.Expr {
 	p.nextToken()
 
 	// Parse property name (can be identifier or dynamic)
-	property := p.parseExpression(POSTFIX)
+	property := p.parseMemberName()
 
 	// Check if this is a method call
 	if p.peekTokenIs(lexer.LPAREN) {
@@ -568,19 +748,18 @@ func (p *Parser) parseNullsafePropertyOrMethodCall(left ast.Expr) ast.Expr {
 	token := p.curToken
 	p.nextToken()
 
-	property := p.parseExpression(POSTFIX)
+	property := p.parseMemberName()
 
 	// Check if this is a method call
 	if p.peekTokenIs(lexer.LPAREN) {
 		p.nextToken()
 
-		// For nullsafe method calls, we wrap in NullsafePropertyExpression
-		// The actual method call handling will be in the VM
 		return &ast.MethodCallExpression{
 			Token:     token,
 			Object:    left,
 			Method:    property,
 			Arguments: p.parseCallArguments(),
+			Nullsafe:  true,
 		}
 	}
 
@@ -592,10 +771,33 @@ func (p *Parser) parseNullsafePropertyOrMethodCall(left ast.Expr) ast.Expr {
 	}
 }
 
+// parseMemberName parses the name after -> or ?->: an identifier, a
+// variable holding the name, or an expression in braces: $obj->{$a . $b}
+func (p *Parser) parseMemberName() ast.Expr {
+	if !p.curTokenIs(lexer.LBRACE) {
+		return p.parseExpression(POSTFIX)
+	}
+	p.nextToken()
+	name := p.parseExpression(LOWEST)
+	if !p.expectPeek(lexer.RBRACE) {
+		return nil
+	}
+	return name
+}
+
 func (p *Parser) parseStaticAccessOrCall(left ast.Expr) ast.Expr {
 	token := p.curToken
 	p.nextToken()
 
+	// Class name resolution: Foo::class
+	if p.curTokenIs(lexer.CLASS) {
+		return &ast.StaticPropertyExpression{
+			Token:    token,
+			Class:    left,
+			Property: &ast.Identifier{Token: p.curToken, Value: "class"},
+		}
+	}
+
 	// Parse member (method, property, or constant)
 	member := p.parseExpression(POSTFIX)
 
@@ -636,12 +838,26 @@ func (p *Parser) parseCallArguments() []ast.Expr {
 	}
 
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+
+	// First-class callable syntax: func(...)
+	if p.curTokenIs(lexer.ELLIPSIS) && p.peekTokenIs(lexer.RPAREN) {
+		placeholder := &ast.VariadicPlaceholder{Token: p.curToken}
+		p.nextToken()
+		return []ast.Expr{placeholder}
+	}
+
+	args = append(args, p.parseCallArgument())
 
 	for p.peekTokenIs(lexer.COMMA) {
 		p.nextToken() // consume comma
+
+		// Allow trailing comma
+		if p.peekTokenIs(lexer.RPAREN) {
+			break
+		}
+
 		p.nextToken() // move to next argument
-		args = append(args, p.parseExpression(LOWEST))
+		args = append(args, p.parseCallArgument())
 	}
 
 	if !p.expectPeek(lexer.RPAREN) {
@@ -651,6 +867,47 @@ func (p *Parser) parseCallArguments() []ast.Expr {
 	return args
 }
 
+// parseCallArgument parses a call argument: an expression, an unpacked
+// ...$args, or a named argument name: $value. Any identifier-like token,
+// including reserved words, can name a parameter.
+func (p *Parser) parseCallArgument() ast.Expr {
+	if p.curTokenIs(lexer.ELLIPSIS) {
+		spread := &ast.SpreadExpression{Token: p.curToken}
+		p.nextToken()
+		spread.Value = p.parseExpression(LOWEST)
+		return spread
+	}
+
+	if p.peekTokenIs(lexer.COLON) && !p.curTokenIs(lexer.STRING) && !p.curTokenIs(lexer.VARIABLE) &&
+		isIdentifierLike(p.curToken.Literal) {
+		named := &ast.NamedArgument{Token: p.curToken, Name: p.curToken.Literal}
+		p.nextToken() // consume name
+		p.nextToken() // move past ':'
+		named.Value = p.parseExpression(LOWEST)
+		return named
+	}
+
+	return p.parseExpression(LOWEST)
+}
+
+// isIdentifierLike reports whether a token literal has the form of a PHP
+// label ([a-zA-Z_][a-zA-Z0-9_]*, plus bytes >= 0x80)
+func isIdentifierLike(literal string) bool {
+	if literal == "" {
+		return false
+	}
+	for i := 0; i < len(literal); i++ {
+		ch := literal[i]
+		switch {
+		case ch == '_', ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= 0x80:
+		case ch >= '0' && ch <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 func (p *Parser) parseInstanceofExpression(left ast.Expr) ast.Expr {
 	expression := &ast.InstanceofExpression{
 		Token: p.curToken,
@@ -747,14 +1004,11 @@ func (p *Parser) parseUseClause() []*ast.UseClause {
 
 		// Expect variable
 		if !p.curTokenIs(lexer.VARIABLE) {
-			p.errors = append(p.errors, "expected variable in use clause, got "+p.curToken.Literal)
+			p.report("expected variable in use clause, got "+p.curToken.Literal, p.curToken, lexer.VARIABLE)
 			return nil
 		}
 
-		useClause.Variable = &ast.Variable{
-			Token: p.curToken,
-			Name:  p.curToken.Literal,
-		}
+		useClause.Variable = p.parseVariable().(*ast.Variable)
 
 		useClauses = append(useClauses, useClause)
 
@@ -849,15 +1103,3 @@ func (p *Parser) parseStaticClosureOrProperty() ast.Expr {
 		Value: staticToken.Literal,
 	}
 }
-
-// Helper functions for string interpolation
-
-// isLetter checks if a byte is a letter (a-z, A-Z)
-func isLetter(ch byte) bool {
-	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
-}
-
-// isDigit checks if a byte is a digit (0-9)
-func isDigit(ch byte) bool {
-	return ch >= '0' && ch <= '9'
-}
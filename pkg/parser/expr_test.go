@@ -213,6 +213,14 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"<?php 2 ** 3 ** 2",
 			"(2 ** (3 ** 2))",
 		},
+		{
+			"<?php a ?? b ?? c",
+			"(a ?? (b ?? c))",
+		},
+		{
+			"<?php a ?? b . c",
+			"(a ?? (b . c))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -485,6 +493,29 @@ func TestMethodCallExpression(t *testing.T) {
 	}
 }
 
+func TestNullsafeChain(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"<?php $a?->b;", "($a?->b)"},
+		{"<?php $a?->b->c;", "(($a?->b)->c)"},
+		{"<?php $a?->b()->c;", "($a?->b(...)->c)"},
+		{"<?php $a->b?->c();", "($a->b)?->c(...)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "test.php")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
 func TestCallExpression(t *testing.T) {
 	input := `<?php add(1, 2 * 3, 4 + 5);`
 
@@ -523,6 +554,72 @@ func TestCallExpression(t *testing.T) {
 	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
 }
 
+func TestCallArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"<?php f(...$args);", []string{"...$args"}},
+		{"<?php f(1, ...$rest, b: 2);", []string{"1", "...$rest", "b: 2"}},
+		{"<?php f(b: 2, a: $x ? 1 : 0);", []string{"b: 2", "a: ($x ? 1 : 0)"}},
+		{"<?php f(array: [], default: null,);", []string{"array: [array]", "default: null"}},
+		{"<?php f(X ? 1 : 2);", []string{"(X ? 1 : 2)"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "test.php")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		call, ok := stmt.Expression.(*ast.CallExpression)
+		if !ok {
+			t.Fatalf("%s: expected *ast.CallExpression. got=%T", tt.input, stmt.Expression)
+		}
+		if len(call.Arguments) != len(tt.expected) {
+			t.Fatalf("%s: expected %d arguments. got=%d", tt.input, len(tt.expected), len(call.Arguments))
+		}
+		for i, arg := range call.Arguments {
+			if arg.String() != tt.expected[i] {
+				t.Errorf("%s: argument %d expected=%q, got=%q", tt.input, i, tt.expected[i], arg.String())
+			}
+		}
+	}
+}
+
+func TestFirstClassCallableSyntax(t *testing.T) {
+	tests := []struct {
+		input    string
+		checkArg func(ast.Expr) []ast.Expr
+	}{
+		{`<?php strlen(...);`, func(e ast.Expr) []ast.Expr { return e.(*ast.CallExpression).Arguments }},
+		{`<?php $obj->method(...);`, func(e ast.Expr) []ast.Expr { return e.(*ast.MethodCallExpression).Arguments }},
+		{`<?php Foo::bar(...);`, func(e ast.Expr) []ast.Expr { return e.(*ast.StaticCallExpression).Arguments }},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "test.php")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%s: expected 1 statement, got=%d", tt.input, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("%s: not ast.ExpressionStatement. got=%T", tt.input, program.Statements[0])
+		}
+
+		args := tt.checkArg(stmt.Expression)
+		if !ast.IsFirstClassCallable(args) {
+			t.Errorf("%s: expected first-class callable arguments, got=%v", tt.input, args)
+		}
+	}
+}
+
 func TestStaticCallExpression(t *testing.T) {
 	input := `<?php MyClass::staticMethod(1, 2);`
 
@@ -650,6 +747,162 @@ func TestCastExpression(t *testing.T) {
 	}
 }
 
+func TestIncludeExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		kind  string
+		path  string
+	}{
+		{"<?php include 'a.php';", "include", "a.php"},
+		{"<?php include_once $file;", "include_once", "$file"},
+		{"<?php require 'lib/' . 'b.php';", "require", "(lib/ . b.php)"},
+		{"<?php require_once('c.php');", "require_once", "(c.php)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "test.php")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d\n",
+				len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+				program.Statements[0])
+		}
+
+		include, ok := stmt.Expression.(*ast.IncludeExpression)
+		if !ok {
+			t.Fatalf("exp not *ast.IncludeExpression. got=%T", stmt.Expression)
+		}
+
+		if include.Kind != tt.kind {
+			t.Errorf("include.Kind not '%s'. got=%s", tt.kind, include.Kind)
+		}
+		if include.Path.String() != tt.path {
+			t.Errorf("include.Path not %s. got=%s", tt.path, include.Path.String())
+		}
+	}
+}
+
+func TestExitExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"<?php exit;", "exit"},
+		{"<?php exit();", "exit"},
+		{"<?php exit(3);", "exit(3)"},
+		{"<?php die('bye');", "die(bye)"},
+		{"<?php $f or die;", "($f or die)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "test.php")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d\n",
+				len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+				program.Statements[0])
+		}
+		if stmt.Expression.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.Expression.String())
+		}
+	}
+}
+
+func TestIssetAndEmptyExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"<?php isset($a);", "isset($a)"},
+		{"<?php isset($a['x']['y'], $b?->c,);", "isset((($a[x])[y]), ($b?->c))"},
+		{"<?php !isset($a) && empty($b);", "((!isset($a)) && empty($b))"},
+		{"<?php empty($a . $b);", "empty(($a . $b))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "test.php")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	for _, input := range []string{"<?php isset();", "<?php empty();"} {
+		p := New(lexer.New(input, "test.php"))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected a parse error", input)
+		}
+	}
+}
+
+func TestListAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"<?php list($a, $b) = $arr;", "(list($a, $b) = $arr)"},
+		{"<?php [$a, [$b, $c]] = $x;", "(list($a, list($b, $c)) = $x)"},
+		{"<?php ['k' => $v, 'n' => list($m)] = $x;", "(list(k => $v, n => list($m)) = $x)"},
+		{"<?php [, $b, , $d] = $x;", "(list(, $b, , $d) = $x)"},
+		{"<?php list($a, ) = $x;", "(list($a) = $x)"},
+		{"<?php [$a, &$b] = $x;", "(list($a, &$b) = $x)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "test.php")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d\n",
+				len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+				program.Statements[0])
+		}
+		if stmt.Expression.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.Expression.String())
+		}
+	}
+}
+
+func TestArrayLiteralIsNotList(t *testing.T) {
+	l := lexer.New("<?php $x = [$a, $b];", "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	assign := stmt.Expression.(*ast.AssignmentExpression)
+	if _, ok := assign.Right.(*ast.ArrayExpression); !ok {
+		t.Errorf("right side should stay an array literal. got=%T", assign.Right)
+	}
+}
+
 func TestGroupedExpression(t *testing.T) {
 	input := `<?php (5 + 5) * 2;`
 
@@ -768,3 +1021,182 @@ func testInfixExpression(t *testing.T, exp ast.Expr, left interface{},
 
 	return true
 }
+
+func TestClassNameConstantExpression(t *testing.T) {
+	input := `<?php Foo\Bar::class;`
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	expr, ok := stmt.Expression.(*ast.StaticPropertyExpression)
+	if !ok {
+		t.Fatalf("expression is not *ast.StaticPropertyExpression. got=%T", stmt.Expression)
+	}
+	if expr.Class.String() != "Foo\\Bar" {
+		t.Errorf("class wrong. expected=Foo\\Bar, got=%s", expr.Class.String())
+	}
+	if prop, ok := expr.Property.(*ast.Identifier); !ok || prop.Value != "class" {
+		t.Errorf("property is not the 'class' identifier. got=%v", expr.Property)
+	}
+}
+
+func TestInterpolatedStringExpression(t *testing.T) {
+	input := `<?php "Hi $name, {$arr['k']} $list[0] $obj->prop\t!";`
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	str, ok := stmt.Expression.(*ast.InterpolatedStringExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpolatedStringExpression. got=%T", stmt.Expression)
+	}
+
+	if len(str.Parts) != 9 {
+		t.Fatalf("wrong number of parts. expected=9, got=%d", len(str.Parts))
+	}
+
+	if literal, ok := str.Parts[0].(*ast.StringLiteral); !ok || literal.Value != "Hi " {
+		t.Errorf("part[0] is not \"Hi \". got=%v", str.Parts[0])
+	}
+	testVariable(t, str.Parts[1], "name")
+
+	index, ok := str.Parts[3].(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("part[3] not *ast.IndexExpression. got=%T", str.Parts[3])
+	}
+	testVariable(t, index.Left, "arr")
+	if key, ok := index.Index.(*ast.StringLiteral); !ok || key.Value != "k" {
+		t.Errorf("part[3] index is not 'k'. got=%v", index.Index)
+	}
+
+	index, ok = str.Parts[5].(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("part[5] not *ast.IndexExpression. got=%T", str.Parts[5])
+	}
+	testIntegerLiteral(t, index.Index, 0)
+
+	if _, ok := str.Parts[7].(*ast.PropertyExpression); !ok {
+		t.Errorf("part[7] not *ast.PropertyExpression. got=%T", str.Parts[7])
+	}
+	if literal, ok := str.Parts[8].(*ast.StringLiteral); !ok || literal.Value != "\t!" {
+		t.Errorf("part[8] is not \"\\t!\". got=%v", str.Parts[8])
+	}
+}
+
+func TestStringsWithoutInterpolation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`<?php 'Hi $name';`, "Hi $name"},
+		{`<?php "Hi \$name\t";`, "Hi $name\t"},
+		{"<?php <<<'EOT'\nHi $name\\t\nEOT;", "Hi $name\\t"},
+		{"<?php <<<EOT\nHi \\$name\\t\nEOT;", "Hi $name\t"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "test.php")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.StringLiteral)
+		if !ok {
+			t.Fatalf("%s: exp not *ast.StringLiteral. got=%T", tt.input, stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("%s: value wrong. expected=%q, got=%q", tt.input, tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestInterpolatedHeredocLines(t *testing.T) {
+	input := "<?php\n$s = <<<EOT\nfirst\nsecond {$b}\nEOT;\n"
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	assign := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.AssignmentExpression)
+	str, ok := assign.Right.(*ast.InterpolatedStringExpression)
+	if !ok {
+		t.Fatalf("value not *ast.InterpolatedStringExpression. got=%T", assign.Right)
+	}
+
+	variable, ok := str.Parts[1].(*ast.Variable)
+	if !ok {
+		t.Fatalf("part[1] not *ast.Variable. got=%T", str.Parts[1])
+	}
+	if variable.Token.Pos.Line != 4 {
+		t.Errorf("embedded variable on wrong line. expected=4, got=%d", variable.Token.Pos.Line)
+	}
+}
+
+func TestInterpolatedStringErrors(t *testing.T) {
+	l := lexer.New(`<?php "a {$b +} c";`, "test.php")
+	p := New(l)
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Error("expected an error for an invalid embedded expression")
+	}
+}
+
+func TestMagicConstants(t *testing.T) {
+	names := []string{"__LINE__", "__FILE__", "__DIR__", "__FUNCTION__", "__CLASS__", "__TRAIT__", "__METHOD__", "__NAMESPACE__"}
+	for _, name := range names {
+		l := lexer.New("<?php "+name+";", "test.php")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		expr := program.Statements[0].(*ast.ExpressionStatement).Expression
+		magic, ok := expr.(*ast.MagicConstant)
+		if !ok {
+			t.Fatalf("%s: expression is not *ast.MagicConstant. got=%T", name, expr)
+		}
+		if magic.String() != name {
+			t.Errorf("expected %s, got %s", name, magic.String())
+		}
+	}
+}
+
+func TestVariableVariablesAndDynamicMembers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`$$name;`, `$$name`},
+		{`$$$name;`, `$$$name`},
+		{`${'a' . 'b'};`, `${(a . b)}`},
+		{`$$name[0];`, `($$name[0])`},
+		{`$obj->{$prefix . 'Name'};`, `($obj->($prefix . Name))`},
+		{`$obj?->{$prop};`, `($obj?->$prop)`},
+		{`$obj->$method();`, `$obj->$method(...)`},
+		{`$class::$$prop;`, `($class::$$prop)`},
+		{`new $className;`, `new $className(...)`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New("<?php "+tt.input, "test.php")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%s: expected 1 statement, got %d", tt.input, len(program.Statements))
+		}
+		expr := program.Statements[0].(*ast.ExpressionStatement).Expression
+		if got := expr.String(); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
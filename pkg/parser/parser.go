@@ -8,15 +8,27 @@ import (
 
 // Parser parses PHP source code into an Abstract Syntax Tree (AST)
 type Parser struct {
-	l      *lexer.Lexer
-	errors []string
+	l           *lexer.Lexer
+	errors      []string
+	diagnostics []*Diagnostic
 
 	curToken  lexer.Token
 	peekToken lexer.Token
 
-	// For error recovery
+	// For error recovery: set by an error, cleared at the next statement
+	// boundary. Errors in panic mode are not reported, as they usually
+	// follow from the first.
 	panicMode bool
 
+	// Doc comment waiting for the declaration it documents
+	docComment string
+
+	// Comments skipped between tokens, in source order
+	comments []lexer.Token
+
+	// Tokens read, with their trivia, when the lexer keeps trivia
+	tokens []lexer.Token
+
 	// Pratt parsing function maps
 	prefixParseFns map[lexer.TokenType]prefixParseFn
 	infixParseFns  map[lexer.TokenType]infixParseFn
@@ -44,6 +56,7 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{
 		Statements: []ast.Stmt{},
 	}
+	start := p.curToken.Pos
 
 	// Skip PHP opening tag if present
 	if p.curTokenIs(lexer.OPEN_TAG) || p.curTokenIs(lexer.OPEN_TAG_ECHO) {
@@ -55,14 +68,26 @@ func (p *Parser) ParseProgram() *ast.Program {
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
+		p.recoverStatement()
 		p.nextToken()
 	}
+	p.setSpan(program, start)
+	completeSpans(program)
 
 	return program
 }
 
-// parseStatement parses a single statement
+// parseStatement parses a single statement, recording its span
 func (p *Parser) parseStatement() ast.Stmt {
+	start := p.curToken.Pos
+	stmt := p.parseStatementKind()
+	p.setSpan(stmt, start)
+	return stmt
+}
+
+// parseStatementKind parses a single statement of the kind its first
+// token introduces
+func (p *Parser) parseStatementKind() ast.Stmt {
 	switch p.curToken.Type {
 	case lexer.LBRACE:
 		return p.parseBlockStatement()
@@ -92,26 +117,59 @@ func (p *Parser) parseStatement() ast.Stmt {
 		return p.parseThrowStatement()
 	case lexer.FUNCTION:
 		return p.parseFunctionDeclaration()
+	case lexer.ATTRIBUTE_START:
+		return p.parseAttributedStatement()
 	case lexer.CLASS:
 		return p.parseClassDeclaration()
 	case lexer.INTERFACE:
 		return p.parseInterfaceDeclaration()
 	case lexer.TRAIT:
 		return p.parseTraitDeclaration()
+	case lexer.ENUM:
+		// enum is only a keyword before the enum name
+		if p.peekTokenIs(lexer.IDENT) {
+			return p.parseEnumDeclaration()
+		}
+		return p.parseExpressionStatement()
 	case lexer.ABSTRACT, lexer.FINAL:
 		// Handle abstract/final class declarations
 		return p.parseClassDeclarationWithModifiers()
+	case lexer.READONLY:
+		// readonly class (PHP 8.2); readonly() is otherwise a function call
+		if p.peekTokenIs(lexer.CLASS) || p.peekTokenIs(lexer.ABSTRACT) || p.peekTokenIs(lexer.FINAL) {
+			return p.parseClassDeclarationWithModifiers()
+		}
+		return p.parseExpressionStatement()
+	case lexer.NAMESPACE:
+		return p.parseNamespaceStatement()
+	case lexer.USE:
+		return p.parseUseStatement()
+	case lexer.GLOBAL:
+		return p.parseGlobalStatement()
+	case lexer.UNSET:
+		return p.parseUnsetStatement()
+	case lexer.DECLARE:
+		return p.parseDeclareStatement()
+	case lexer.CONST:
+		return p.parseConstStatement()
+	case lexer.STATIC:
+		// static $x = ...; declares static variables, anything else
+		// (static::, static function) is an expression
+		if p.peekTokenIs(lexer.VARIABLE) {
+			return p.parseStaticVarStatement()
+		}
+		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
-// parseClassDeclarationWithModifiers handles abstract/final class declarations
+// parseClassDeclarationWithModifiers handles abstract/final/readonly class declarations
 func (p *Parser) parseClassDeclarationWithModifiers() *ast.ClassDeclaration {
 	modifiers := []string{}
 
-	// Collect abstract/final modifiers
-	for p.curTokenIs(lexer.ABSTRACT) || p.curTokenIs(lexer.FINAL) {
+	// Collect abstract/final/readonly modifiers
+	for p.curTokenIs(lexer.ABSTRACT) || p.curTokenIs(lexer.FINAL) || p.curTokenIs(lexer.READONLY) {
 		modifiers = append(modifiers, p.curToken.Literal)
 		p.nextToken()
 	}
@@ -152,10 +210,55 @@ func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
 
+	// A doc comment documents the next declaration of the statement or
+	// member it precedes
+	switch p.curToken.Type {
+	case lexer.SEMICOLON, lexer.LBRACE, lexer.RBRACE:
+		p.docComment = ""
+	}
+
 	// Skip comments automatically
-	for p.peekToken.Type == lexer.COMMENT {
+	for p.peekToken.Type == lexer.COMMENT || p.peekToken.Type == lexer.DOC_COMMENT {
+		if p.peekToken.Type == lexer.DOC_COMMENT {
+			p.docComment = p.peekToken.Literal
+		}
+		p.comments = append(p.comments, p.peekToken)
 		p.peekToken = p.l.NextToken()
 	}
+
+	// In trivia mode comments are the trivia of the token they precede
+	for _, t := range p.peekToken.Leading {
+		if t.Type == lexer.DOC_COMMENT {
+			p.docComment = t.Literal
+		}
+		if t.Type == lexer.COMMENT || t.Type == lexer.DOC_COMMENT {
+			p.comments = append(p.comments, t)
+		}
+	}
+	if p.l.KeepsTrivia() && (len(p.tokens) == 0 || p.tokens[len(p.tokens)-1].Type != lexer.EOF) {
+		p.tokens = append(p.tokens, p.peekToken)
+	}
+}
+
+// peekSecondToken returns the token after peekToken without consuming
+// anything, for the few constructs that need two tokens of lookahead
+func (p *Parser) peekSecondToken() lexer.Token {
+	saved := *p.l
+	defer func() { *p.l = saved }()
+
+	tok := p.l.NextToken()
+	for tok.Type == lexer.COMMENT || tok.Type == lexer.DOC_COMMENT {
+		tok = p.l.NextToken()
+	}
+	return tok
+}
+
+// takeDocComment returns the pending doc comment for the declaration
+// being parsed, so that it does not document the next one too
+func (p *Parser) takeDocComment() string {
+	doc := p.docComment
+	p.docComment = ""
+	return doc
 }
 
 // curTokenIs checks if the current token is of the given type
@@ -183,7 +286,7 @@ func (p *Parser) expectCurrent(t lexer.TokenType) bool {
 	if p.curTokenIs(t) {
 		return true
 	}
-	p.error(fmt.Sprintf("expected token %s, got %s", t, p.curToken.Type))
+	p.report(fmt.Sprintf("expected token %s, got %s", t, p.curToken.Type), p.curToken, t)
 	return false
 }
 
@@ -191,15 +294,34 @@ func (p *Parser) expectCurrent(t lexer.TokenType) bool {
 
 // error adds an error message to the parser's error list
 func (p *Parser) error(msg string) {
-	errMsg := fmt.Sprintf("[%s] Parse error: %s", p.curToken.Pos, msg)
-	p.errors = append(p.errors, errMsg)
+	p.report(msg, p.curToken)
 }
 
 // peekError adds an error about an unexpected peek token
 func (p *Parser) peekError(t lexer.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
 		t, p.peekToken.Type)
-	p.error(msg)
+	p.report(msg, p.peekToken, t)
+}
+
+// report records an error found at a token, and the tokens expected there,
+// unless the parser is in panic mode. The message of Errors keeps the
+// position of the current token.
+func (p *Parser) report(msg string, tok lexer.Token, expected ...lexer.TokenType) {
+	if p.panicMode {
+		return
+	}
+	p.panicMode = true
+
+	errMsg := fmt.Sprintf("[%s] Parse error: %s", p.curToken.Pos, msg)
+	p.errors = append(p.errors, errMsg)
+	p.diagnostics = append(p.diagnostics, &Diagnostic{
+		Pos:      tok.Pos,
+		Message:  msg,
+		Token:    tok,
+		Expected: expected,
+		Excerpt:  excerpt(p.l.Input(), tok.Pos, len(tok.Literal)),
+	})
 }
 
 // Errors returns all parsing errors
@@ -207,6 +329,18 @@ func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// Diagnostics returns the parsing errors with their positions, offending
+// tokens and source excerpts, in the order of Errors
+func (p *Parser) Diagnostics() []*Diagnostic {
+	return p.diagnostics
+}
+
+// Comments returns the comments and doc comments of the source parsed so
+// far, which are not part of the AST, in source order
+func (p *Parser) Comments() []lexer.Token {
+	return p.comments
+}
+
 // HasErrors returns true if there are any parsing errors
 func (p *Parser) HasErrors() bool {
 	return len(p.errors) > 0
@@ -214,6 +348,15 @@ func (p *Parser) HasErrors() bool {
 
 // Error recovery methods
 
+// recoverStatement is called after each statement of a statement list:
+// after an error it skips to the next statement boundary, so the errors
+// of the following statements are reported as well
+func (p *Parser) recoverStatement() {
+	if p.panicMode {
+		p.synchronize()
+	}
+}
+
 // synchronize attempts to recover from a parse error by skipping tokens
 // until we reach a statement boundary
 func (p *Parser) synchronize() {
@@ -227,11 +370,14 @@ func (p *Parser) synchronize() {
 
 		// Check for statement-starting keywords
 		switch p.peekToken.Type {
-		case lexer.CLASS, lexer.FUNCTION, lexer.INTERFACE, lexer.TRAIT,
+		case lexer.CLASS, lexer.FUNCTION, lexer.INTERFACE, lexer.TRAIT, lexer.ENUM,
 			lexer.NAMESPACE, lexer.USE, lexer.CONST,
 			lexer.IF, lexer.WHILE, lexer.FOR, lexer.FOREACH,
 			lexer.SWITCH, lexer.RETURN, lexer.BREAK, lexer.CONTINUE,
-			lexer.ECHO, lexer.TRY, lexer.THROW:
+			lexer.ECHO, lexer.TRY, lexer.THROW,
+			lexer.RBRACE, lexer.CASE, lexer.DEFAULT,
+			lexer.ENDIF, lexer.ENDWHILE, lexer.ENDFOR, lexer.ENDFOREACH,
+			lexer.ENDSWITCH, lexer.ENDDECLARE:
 			return
 		}
 
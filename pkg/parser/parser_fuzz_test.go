@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+// FuzzParser checks that the parser reports errors in malformed input
+// rather than panicking
+func FuzzParser(f *testing.F) {
+	for _, seed := range []string{
+		"<?php",
+		"<?php echo 1 + 2 * 3, \"\\n\";",
+		"<?php $a = [1, 'k' => [2, 3], ...$b]; [$x, [, $y]] = $a; list('k' => $z) = $a;",
+		"<?php function f(int|string $x = 0, &...$rest): static { yield from g(); return fn() => $x; }",
+		"<?php abstract class A extends B implements C, D { use T { f as protected g; } public function __construct(private readonly int $x) {} }",
+		"<?php enum Suit: string { case Hearts = 'H'; public function label(): string { return match($this) { self::Hearts => 'h', default => '' }; } }",
+		"<?php namespace App\\Models; use Foo\\{Bar, Baz as Q}; interface I { const X = 1; }",
+		"<?php try { throw new E(); } catch (A|B $e) { } finally { } switch ($x) { case 1: break; default: continue 2; }",
+		"<?php foreach ($a as $k => &$v) { while (true) { do { } while (0); } for ($i = 0; $i < 3; $i++); }",
+		"<?php $o?->m()::$p[0]{1}; new class($a) extends B {}; static fn&($x) => $x; #[A(1)] function g() {}",
+		"<?php if ($a): elseif ($b): else: endif; declare(strict_types=1); goto end; end:",
+		"<?php echo <<<EOT\n{$a['b']} $c->d\nEOT;",
+		"<?php function (",
+		"<?php class { public function",
+		"<?php $a = [1, 2",
+		"<?php match ($x) { 1, 2 => , }",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		p := New(lexer.New(input, "fuzz.php"))
+		p.ParseProgram()
+	})
+}
+
+// FuzzSyntaxTree checks that the concrete syntax tree reproduces any input
+func FuzzSyntaxTree(f *testing.F) {
+	for _, seed := range []string{
+		"<?php",
+		"<html>\n<?php /** doc */ function f() {} // end\n?>\n<p><?= $x ?></p>\n",
+		"<?php echo 'a\\'b', \"c\\n{$d}\"; /* unterminated",
+		"<?php $x = <<<EOT\n  a $b\n  EOT;\n# comment",
+		"x <? y ?> z <?php",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		l := lexer.New(input, "fuzz.php")
+		l.KeepTrivia()
+		p := New(l)
+		if text := p.SyntaxTree(p.ParseProgram()).Text(); text != input {
+			t.Errorf("Expected %q, got %q", input, text)
+		}
+	})
+}
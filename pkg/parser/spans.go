@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"reflect"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+// spanner is a node whose span can be set: any node type, through the
+// ast.Span it embeds
+type spanner interface {
+	SetSpan(start, end lexer.Position)
+}
+
+// setSpan records that a node extends from start to the end of the
+// current token, the last one it was parsed from
+func (p *Parser) setSpan(node ast.Node, start lexer.Position) {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return
+	}
+	if s, ok := node.(spanner); ok {
+		s.SetSpan(start, p.curToken.End)
+	}
+}
+
+// spanFrame is a node being completed by completeSpans, with the extent of
+// its children so far
+type spanFrame struct {
+	node       ast.Node
+	start, end lexer.Position
+}
+
+// completeSpans sets the spans the parser did not record: those of the
+// nodes parsed as parts of a statement or expression, such as names,
+// parameters and types. Such a node extends from its token over its
+// children.
+func completeSpans(root ast.Node) {
+	var stack []*spanFrame
+	ast.Inspect(root, func(node ast.Node) bool {
+		if node != nil {
+			stack = append(stack, &spanFrame{node: node})
+			return true
+		}
+
+		frame := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		node = frame.node
+		if !node.Pos().IsValid() {
+			start, end := frame.start, frame.end
+			if tok, ok := nodeToken(node); ok {
+				start, end = earliest(start, tok.Pos), latest(end, tok.End)
+			}
+			if s, ok := node.(spanner); ok {
+				s.SetSpan(start, end)
+			}
+		}
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			parent.start, parent.end = earliest(parent.start, node.Pos()), latest(parent.end, node.End())
+		}
+		return true
+	})
+}
+
+// nodeToken returns the Token field of a node
+func nodeToken(node ast.Node) (lexer.Token, bool) {
+	field := reflect.ValueOf(node).Elem().FieldByName("Token")
+	if !field.IsValid() {
+		return lexer.Token{}, false
+	}
+	tok, ok := field.Interface().(lexer.Token)
+	return tok, ok && tok.Pos.IsValid()
+}
+
+// earliest returns the earlier of two positions, ignoring unset ones
+func earliest(a, b lexer.Position) lexer.Position {
+	if !a.IsValid() || b.IsValid() && b.Before(a) {
+		return b
+	}
+	return a
+}
+
+// latest returns the later of two positions, ignoring unset ones
+func latest(a, b lexer.Position) lexer.Position {
+	if !a.IsValid() || b.IsValid() && b.After(a) {
+		return b
+	}
+	return a
+}
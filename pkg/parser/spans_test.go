@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+const spanSource = `<?php
+namespace App;
+
+#[Entity]
+final class User extends Model implements JsonSerializable {
+    private ?string $name = null;
+
+    public static function find(int|string $id, ...$rest): ?static {
+        $users = array_map(fn($u) => $u->name, self::$cache[$id] ?? []);
+        foreach ($users as $key => $user) {
+            echo "Found {$user}\n";
+        }
+        return new static(...$rest);
+    }
+}
+
+function main() {
+    try {
+        throw new \Exception("failed");
+    } catch (A|B $e) {
+        $x = match (true) { default => 1 };
+    }
+}
+`
+
+func TestSpans_EveryNode(t *testing.T) {
+	p := New(lexer.New(spanSource, "spans.php"))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node == nil {
+			return true
+		}
+		start, end := node.Pos(), node.End()
+		if start.Line == 0 || end.Line == 0 {
+			t.Errorf("%T %q has no span", node, node.String())
+		} else if end.Offset < start.Offset {
+			t.Errorf("%T %q ends at %d before its start %d", node, node.String(), end.Offset, start.Offset)
+		}
+		return true
+	})
+}
+
+func TestSpans_Source(t *testing.T) {
+	program := New(lexer.New(spanSource, "spans.php")).ParseProgram()
+
+	spans := map[string]bool{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node != nil {
+			spans[spanSource[node.Pos().Offset:node.End().Offset]] = true
+		}
+		return true
+	})
+
+	tests := []string{
+		"namespace App;",
+		"private ?string $name = null;",
+		"?string",
+		"int|string",
+		"public static function find(int|string $id, ...$rest): ?static {\n        $users",
+		"array_map(fn($u) => $u->name, self::$cache[$id] ?? [])",
+		"fn($u) => $u->name",
+		"self::$cache[$id] ?? []",
+		"$users = array_map(fn($u) => $u->name, self::$cache[$id] ?? []);",
+		`echo "Found {$user}\n";`,
+		"new static(...$rest)",
+		"...$rest",
+		`new \Exception("failed")`,
+		"match (true) { default => 1 }",
+		"{\n        $x = match (true) { default => 1 };\n    }",
+		"Entity",
+		"main",
+	}
+	for _, text := range tests {
+		found := spans[text]
+		if !found {
+			// Multi-line expectations only need to match the start of a span
+			for span := range spans {
+				if len(span) > len(text) && span[:len(text)] == text && text[len(text)-1] != ';' {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			t.Errorf("no node spans %q", text)
+		}
+	}
+
+	if start, end := program.Pos(), program.End(); start.Offset != 0 || end.Offset != len(spanSource) {
+		t.Errorf("expected the program to span the source, got %d-%d", start.Offset, end.Offset)
+	}
+}
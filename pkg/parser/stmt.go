@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"strings"
+
 	"github.com/krizos/php-go/pkg/ast"
 	"github.com/krizos/php-go/pkg/lexer"
 )
@@ -32,6 +34,144 @@ func (p *Parser) parseEchoStatement() *ast.EchoStatement {
 	return stmt
 }
 
+// parseStaticVarStatement parses static variable declarations
+// static $a = 1, $b;
+func (p *Parser) parseStaticVarStatement() *ast.StaticVarStatement {
+	stmt := &ast.StaticVarStatement{
+		Token: p.curToken,
+		Vars:  []*ast.StaticVar{},
+	}
+
+	for {
+		if !p.expectPeek(lexer.VARIABLE) {
+			return nil
+		}
+		staticVar := &ast.StaticVar{Name: p.parseVariable().(*ast.Variable)}
+
+		if p.peekTokenIs(lexer.ASSIGN) {
+			p.nextToken() // consume =
+			p.nextToken() // move to initializer
+			staticVar.Value = p.parseExpression(LOWEST)
+		}
+		stmt.Vars = append(stmt.Vars, staticVar)
+
+		if !p.peekTokenIs(lexer.COMMA) {
+			break
+		}
+		p.nextToken() // consume comma
+	}
+
+	// Optional semicolon
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseGlobalStatement parses global $a, $b;
+func (p *Parser) parseGlobalStatement() *ast.GlobalStatement {
+	stmt := &ast.GlobalStatement{
+		Token: p.curToken,
+		Vars:  []*ast.Variable{},
+	}
+
+	for {
+		if !p.expectPeek(lexer.VARIABLE) {
+			return nil
+		}
+		stmt.Vars = append(stmt.Vars, p.parseVariable().(*ast.Variable))
+
+		if !p.peekTokenIs(lexer.COMMA) {
+			break
+		}
+		p.nextToken() // consume comma
+	}
+
+	// Optional semicolon
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseDeclareStatement parses declare(name=value, ...) followed by ;, a
+// block or : ... enddeclare;
+func (p *Parser) parseDeclareStatement() *ast.DeclareStatement {
+	stmt := &ast.DeclareStatement{Token: p.curToken}
+
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil
+	}
+	for {
+		if !p.expectPeek(lexer.IDENT) {
+			return nil
+		}
+		directive := &ast.DeclareDirective{Name: strings.ToLower(p.curToken.Literal)}
+		if !p.expectPeek(lexer.ASSIGN) {
+			return nil
+		}
+		p.nextToken()
+		directive.Value = p.parseExpression(LOWEST)
+		stmt.Directives = append(stmt.Directives, directive)
+
+		if !p.peekTokenIs(lexer.COMMA) {
+			break
+		}
+		p.nextToken() // consume comma
+	}
+	if !p.expectPeek(lexer.RPAREN) {
+		return nil
+	}
+
+	switch {
+	case p.peekTokenIs(lexer.LBRACE):
+		p.nextToken()
+		stmt.Body = p.parseBlockStatement()
+	case p.peekTokenIs(lexer.COLON):
+		p.nextToken()
+		stmt.Body = &ast.BlockStatement{Token: p.curToken}
+		p.nextToken()
+		for !p.curTokenIs(lexer.ENDDECLARE) && !p.curTokenIs(lexer.EOF) {
+			if inner := p.parseStatement(); inner != nil {
+				stmt.Body.Statements = append(stmt.Body.Statements, inner)
+			}
+			p.recoverStatement()
+			p.nextToken()
+		}
+		if !p.curTokenIs(lexer.ENDDECLARE) {
+			p.error("expected 'enddeclare'")
+			return nil
+		}
+		if p.peekTokenIs(lexer.SEMICOLON) {
+			p.nextToken()
+		}
+	case p.peekTokenIs(lexer.SEMICOLON):
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseUnsetStatement parses unset($a, $b['k'], ...);
+func (p *Parser) parseUnsetStatement() *ast.UnsetStatement {
+	stmt := &ast.UnsetStatement{Token: p.curToken}
+
+	vars, ok := p.parseVariableList()
+	if !ok {
+		return nil
+	}
+	stmt.Variables = vars
+
+	// Optional semicolon
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 // parseReturnStatement parses return statement
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{
@@ -307,10 +447,20 @@ func (p *Parser) parseForeachStatement() *ast.ForeachStatement {
 
 	p.nextToken()
 
+	// foreach ($array as &$value) has no key
+	if p.curTokenIs(lexer.BITWISE_AND) {
+		stmt.ByRef = true
+		p.nextToken()
+	}
+
 	// Check for key => value syntax
 	firstExpr := p.parseExpression(LOWEST)
 
 	if p.peekTokenIs(lexer.DOUBLE_ARROW) {
+		if stmt.ByRef {
+			p.peekError(lexer.RPAREN)
+			return nil
+		}
 		// Has key
 		stmt.Key = firstExpr
 
@@ -329,6 +479,11 @@ func (p *Parser) parseForeachStatement() *ast.ForeachStatement {
 		stmt.Value = firstExpr
 	}
 
+	// foreach ($rows as [$id, $name]) destructures each value
+	if array, ok := stmt.Value.(*ast.ArrayExpression); ok {
+		stmt.Value = listFromArray(array)
+	}
+
 	if !p.expectPeek(lexer.RPAREN) {
 		return nil
 	}
@@ -390,6 +545,7 @@ func (p *Parser) parseSwitchStatement() *ast.SwitchStatement {
 				if stmt != nil {
 					caseClause.Body = append(caseClause.Body, stmt)
 				}
+				p.recoverStatement()
 				p.nextToken()
 			}
 
@@ -414,6 +570,7 @@ func (p *Parser) parseSwitchStatement() *ast.SwitchStatement {
 				if stmt != nil {
 					defaultClause.Body = append(defaultClause.Body, stmt)
 				}
+				p.recoverStatement()
 				p.nextToken()
 			}
 
@@ -468,7 +625,7 @@ func (p *Parser) parseMatchExpression() ast.Expr {
 				p.nextToken() // consume comma
 
 				// Check if this is the end (trailing comma) or another condition
-				if p.peekTokenIs(lexer.RBRACE) || p.peekTokenIs(lexer.DEFAULT) {
+				if p.peekTokenIs(lexer.DOUBLE_ARROW) {
 					break
 				}
 
@@ -491,9 +648,10 @@ func (p *Parser) parseMatchExpression() ast.Expr {
 			p.nextToken() // consume comma
 
 			// Check if there's another arm or just trailing comma
-			if !p.peekTokenIs(lexer.RBRACE) {
-				p.nextToken() // move to next arm
+			if p.peekTokenIs(lexer.RBRACE) {
+				break
 			}
+			p.nextToken() // move to next arm
 		} else if p.peekTokenIs(lexer.RBRACE) {
 			break
 		}
@@ -595,6 +753,111 @@ func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
 	return stmt
 }
 
+// parseNamespaceStatement parses a namespace declaration
+// Syntax: namespace Name; | namespace Name { ... } | namespace { ... }
+func (p *Parser) parseNamespaceStatement() *ast.NamespaceStatement {
+	stmt := &ast.NamespaceStatement{Token: p.curToken}
+
+	if p.peekTokenIs(lexer.IDENT) {
+		p.nextToken()
+		stmt.Name = strings.TrimPrefix(p.curToken.Literal, "\\")
+
+		if p.peekTokenIs(lexer.SEMICOLON) {
+			p.nextToken()
+			return stmt
+		}
+	}
+
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+
+	return stmt
+}
+
+// parseUseStatement parses a namespace import
+// Syntax: use [function|const] Name [as Alias], ...;
+//
+//	use Prefix\{Name [as Alias], [function|const] Name, ...};
+func (p *Parser) parseUseStatement() *ast.UseStatement {
+	stmt := &ast.UseStatement{Token: p.curToken}
+	kind := p.parseUseKind()
+
+	for {
+		if !p.expectPeek(lexer.IDENT) {
+			return nil
+		}
+		name := strings.TrimPrefix(p.curToken.Literal, "\\")
+
+		if strings.HasSuffix(name, "\\") && p.peekTokenIs(lexer.LBRACE) {
+			// Group use: use Prefix\{A, B as C}
+			p.nextToken()
+			for !p.peekTokenIs(lexer.RBRACE) {
+				itemKind := kind
+				if itemKind == "" {
+					itemKind = p.parseUseKind()
+				}
+				if !p.expectPeek(lexer.IDENT) {
+					return nil
+				}
+				item := &ast.UseItem{Kind: itemKind, Name: name + p.curToken.Literal}
+				if !p.parseUseAlias(item) {
+					return nil
+				}
+				stmt.Items = append(stmt.Items, item)
+
+				if !p.peekTokenIs(lexer.COMMA) {
+					break
+				}
+				p.nextToken()
+			}
+			if !p.expectPeek(lexer.RBRACE) {
+				return nil
+			}
+		} else {
+			item := &ast.UseItem{Kind: kind, Name: name}
+			if !p.parseUseAlias(item) {
+				return nil
+			}
+			stmt.Items = append(stmt.Items, item)
+		}
+
+		if !p.peekTokenIs(lexer.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseUseKind consumes an optional function/const keyword of a use statement
+func (p *Parser) parseUseKind() string {
+	if p.peekTokenIs(lexer.FUNCTION) || p.peekTokenIs(lexer.CONST) {
+		p.nextToken()
+		return strings.ToLower(p.curToken.Literal)
+	}
+	return ""
+}
+
+// parseUseAlias parses an optional "as Alias" clause of an imported name
+func (p *Parser) parseUseAlias(item *ast.UseItem) bool {
+	if !p.peekTokenIs(lexer.AS) {
+		return true
+	}
+	p.nextToken()
+	if !p.expectPeek(lexer.IDENT) {
+		return false
+	}
+	item.Alias = p.curToken.Literal
+	return true
+}
+
 // parseBlockStatement parses a block of statements { ... }
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{
@@ -609,8 +872,10 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
+		p.recoverStatement()
 		p.nextToken()
 	}
+	p.setSpan(block, block.Token.Pos)
 
 	return block
 }
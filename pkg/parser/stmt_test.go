@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/krizos/php-go/pkg/ast"
@@ -291,9 +292,12 @@ func TestForeachStatement(t *testing.T) {
 	tests := []struct {
 		input  string
 		hasKey bool
+		byRef  bool
 	}{
-		{"<?php foreach ($arr as $value) { echo $value; }", false},
-		{"<?php foreach ($arr as $key => $value) { echo $key, $value; }", true},
+		{"<?php foreach ($arr as $value) { echo $value; }", false, false},
+		{"<?php foreach ($arr as $key => $value) { echo $key, $value; }", true, false},
+		{"<?php foreach ($arr as &$value) { $value++; }", false, true},
+		{"<?php foreach ($arr as $key => &$value) { $value++; }", true, true},
 	}
 
 	for _, tt := range tests {
@@ -326,6 +330,38 @@ func TestForeachStatement(t *testing.T) {
 		if !tt.hasKey && stmt.Key != nil {
 			t.Error("expected nil key, got value")
 		}
+
+		if stmt.ByRef != tt.byRef {
+			t.Errorf("expected ByRef=%v, got %v", tt.byRef, stmt.ByRef)
+		}
+	}
+}
+
+func TestForeachDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"<?php foreach ($rows as [$id, $name]) {}", "list($id, $name)"},
+		{"<?php foreach ($rows as $k => list('id' => $id)) {}", "list(id => $id)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "test.php")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ForeachStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.ForeachStatement. got=%T", program.Statements[0])
+		}
+		if _, ok := stmt.Value.(*ast.ListExpression); !ok {
+			t.Fatalf("foreach value is not *ast.ListExpression. got=%T", stmt.Value)
+		}
+		if stmt.Value.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.Value.String())
+		}
 	}
 }
 
@@ -378,6 +414,95 @@ func TestSwitchStatement(t *testing.T) {
 	}
 }
 
+func TestGlobalStatement(t *testing.T) {
+	input := `<?php
+	global $config, $db;`
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.GlobalStatement)
+	if !ok {
+		t.Fatalf("expected *ast.GlobalStatement, got %T", program.Statements[0])
+	}
+	if len(stmt.Vars) != 2 || stmt.Vars[0].Name != "config" || stmt.Vars[1].Name != "db" {
+		t.Errorf("expected $config and $db, got %v", stmt.Vars)
+	}
+}
+
+func TestUnsetStatement(t *testing.T) {
+	input := `<?php
+	unset($a, $b['k']['l'], $c->d,);`
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.UnsetStatement)
+	if !ok {
+		t.Fatalf("expected *ast.UnsetStatement, got %T", program.Statements[0])
+	}
+	if stmt.String() != "unset($a, (($b[k])[l]), ($c->d));" {
+		t.Errorf("unexpected unset: %s", stmt.String())
+	}
+}
+
+func TestStaticVarStatement(t *testing.T) {
+	input := `<?php
+	static $count = 1 + 2, $cache;
+	static fn() => 1;`
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.StaticVarStatement)
+	if !ok {
+		t.Fatalf("expected *ast.StaticVarStatement, got %T", program.Statements[0])
+	}
+	if len(stmt.Vars) != 2 {
+		t.Fatalf("expected 2 static variables, got %d", len(stmt.Vars))
+	}
+	if stmt.Vars[0].Name.Name != "count" || stmt.Vars[0].Value == nil {
+		t.Errorf("expected $count with an initializer, got %s", stmt.Vars[0].Name.Name)
+	}
+	if stmt.Vars[1].Name.Name != "cache" || stmt.Vars[1].Value != nil {
+		t.Errorf("expected $cache without an initializer, got %s", stmt.Vars[1].Name.Name)
+	}
+	if _, ok := program.Statements[1].(*ast.StaticVarStatement); ok {
+		t.Error("static fn should not parse as a static variable declaration")
+	}
+}
+
+func TestMatchExpressionTrailingComma(t *testing.T) {
+	input := `<?php
+	$result = match ($x) {
+		1, 2, => "low",
+		default => "other",
+	};`
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	assign := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.AssignmentExpression)
+	matchExpr := assign.Right.(*ast.MatchExpression)
+	if len(matchExpr.Arms) != 2 {
+		t.Fatalf("expected 2 match arms, got %d", len(matchExpr.Arms))
+	}
+	if len(matchExpr.Arms[0].Conditions) != 2 {
+		t.Errorf("expected 2 conditions in the first arm, got %d", len(matchExpr.Arms[0].Conditions))
+	}
+}
+
 func TestMatchExpression(t *testing.T) {
 	input := `<?php
 	$result = match ($x) {
@@ -729,3 +854,132 @@ func TestIfElseStatementWithNoBraces(t *testing.T) {
 		}
 	}
 }
+
+func TestNamespaceStatement(t *testing.T) {
+	tests := []struct {
+		input  string
+		name   string
+		braced bool
+	}{
+		{`<?php namespace App\Models;`, "App\\Models", false},
+		{`<?php namespace App { echo 1; }`, "App", true},
+		{`<?php namespace { echo 1; }`, "", true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "test.php")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.NamespaceStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.NamespaceStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Name != tt.name {
+			t.Errorf("namespace name wrong. expected=%q, got=%q", tt.name, stmt.Name)
+		}
+		if (stmt.Body != nil) != tt.braced {
+			t.Errorf("%s: expected braced=%v", tt.input, tt.braced)
+		}
+	}
+}
+
+func TestUseStatement(t *testing.T) {
+	tests := []struct {
+		input string
+		items []ast.UseItem
+	}{
+		{`<?php use Foo\Bar;`, []ast.UseItem{{Name: "Foo\\Bar"}}},
+		{`<?php use \Foo\Bar as Baz, Qux;`, []ast.UseItem{{Name: "Foo\\Bar", Alias: "Baz"}, {Name: "Qux"}}},
+		{`<?php use function Foo\helper;`, []ast.UseItem{{Kind: "function", Name: "Foo\\helper"}}},
+		{`<?php use const Foo\VERSION as V;`, []ast.UseItem{{Kind: "const", Name: "Foo\\VERSION", Alias: "V"}}},
+		{`<?php use Foo\{Bar, Baz as B, function helper};`, []ast.UseItem{
+			{Name: "Foo\\Bar"},
+			{Name: "Foo\\Baz", Alias: "B"},
+			{Kind: "function", Name: "Foo\\helper"},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "test.php")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.UseStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.UseStatement. got=%T", program.Statements[0])
+		}
+		if len(stmt.Items) != len(tt.items) {
+			t.Fatalf("%s: expected %d items, got %d", tt.input, len(tt.items), len(stmt.Items))
+		}
+		for i, item := range stmt.Items {
+			if *item != tt.items[i] {
+				t.Errorf("%s: item %d expected %+v, got %+v", tt.input, i, tt.items[i], *item)
+			}
+		}
+	}
+}
+
+func TestDeclareStatement(t *testing.T) {
+	tests := []struct {
+		input      string
+		directives string
+		body       int // statements in the body, -1 for none
+	}{
+		{`<?php declare(strict_types=1);`, "strict_types=1", -1},
+		{`<?php declare(STRICT_TYPES=0);`, "strict_types=0", -1},
+		{`<?php declare(ticks=1) { $a = 1; $b = 2; }`, "ticks=1", 2},
+		{`<?php declare(ticks=2, encoding='UTF-8'): $a = 1; enddeclare;`, "ticks=2, encoding=UTF-8", 1},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "test.php")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%s: expected 1 statement, got %d", tt.input, len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.DeclareStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.DeclareStatement. got=%T", program.Statements[0])
+		}
+		directives := make([]string, len(stmt.Directives))
+		for i, d := range stmt.Directives {
+			directives[i] = d.Name + "=" + d.Value.String()
+		}
+		if got := strings.Join(directives, ", "); got != tt.directives {
+			t.Errorf("%s: expected directives %q, got %q", tt.input, tt.directives, got)
+		}
+		switch {
+		case tt.body < 0 && stmt.Body != nil:
+			t.Errorf("%s: expected no body", tt.input)
+		case tt.body >= 0 && (stmt.Body == nil || len(stmt.Body.Statements) != tt.body):
+			t.Errorf("%s: expected a body of %d statements, got %v", tt.input, tt.body, stmt.Body)
+		}
+	}
+}
+
+func TestConstStatement(t *testing.T) {
+	l := lexer.New(`<?php const A = 1, B = A * 2;`, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ConstStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ConstStatement. got=%T", program.Statements[0])
+	}
+	if len(stmt.Constants) != 2 {
+		t.Fatalf("expected 2 constants, got %d", len(stmt.Constants))
+	}
+	if stmt.Constants[0].Name.Value != "A" || stmt.Constants[1].Name.Value != "B" {
+		t.Errorf("wrong constant names: %s", stmt.String())
+	}
+	if _, ok := stmt.Constants[1].Value.(*ast.InfixExpression); !ok {
+		t.Errorf("B's value is not *ast.InfixExpression. got=%T", stmt.Constants[1].Value)
+	}
+}
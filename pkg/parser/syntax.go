@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+// SyntaxNode is a node of the concrete syntax tree of a program: an AST
+// node with the tokens and nodes it spans, in source order. With the
+// trivia of its tokens, the tree of a program covers its source exactly.
+type SyntaxNode struct {
+	Node     ast.Node
+	Children []SyntaxElement
+
+	source string
+}
+
+// SyntaxElement is a child of a syntax node: either a token or a node
+type SyntaxElement struct {
+	Token *lexer.Token
+	Node  *SyntaxNode
+}
+
+// SyntaxTree returns the concrete syntax tree of a program parsed from a
+// lexer keeping trivia (see lexer.KeepTrivia), or nil if the lexer does
+// not. A token belongs to the innermost node whose span contains it; AST
+// nodes within a single token, such as the parts of an interpolated
+// string, are not in the tree.
+func (p *Parser) SyntaxTree(program *ast.Program) *SyntaxNode {
+	if !p.l.KeepsTrivia() {
+		return nil
+	}
+	return buildSyntax(program, p.tokens, p.l.Input())
+}
+
+// buildSyntax builds the syntax node of an AST node from its tokens
+func buildSyntax(node ast.Node, tokens []lexer.Token, source string) *SyntaxNode {
+	n := &SyntaxNode{Node: node, source: source}
+	addTokens := func(tokens []lexer.Token) {
+		for i := range tokens {
+			n.Children = append(n.Children, SyntaxElement{Token: &tokens[i]})
+		}
+	}
+
+	i := 0
+	for _, child := range childNodes(node) {
+		start, end := child.Pos().Offset, child.End().Offset
+		j := i
+		for j < len(tokens) && tokens[j].Pos.Offset < start {
+			j++
+		}
+		addTokens(tokens[i:j])
+		i = j
+		for j < len(tokens) && tokens[j].End.Offset <= end {
+			j++
+		}
+		if j > i {
+			n.Children = append(n.Children, SyntaxElement{Node: buildSyntax(child, tokens[i:j], source)})
+			i = j
+		}
+	}
+	addTokens(tokens[i:])
+	return n
+}
+
+// childNodes returns the children of an AST node in source order
+func childNodes(node ast.Node) []ast.Node {
+	var children []ast.Node
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil || n == node {
+			return n == node
+		}
+		children = append(children, n)
+		return false
+	})
+	return children
+}
+
+// Tokens returns the tokens of the node in source order
+func (n *SyntaxNode) Tokens() []lexer.Token {
+	var tokens []lexer.Token
+	for _, child := range n.Children {
+		if child.Token != nil {
+			tokens = append(tokens, *child.Token)
+		} else {
+			tokens = append(tokens, child.Node.Tokens()...)
+		}
+	}
+	return tokens
+}
+
+// Text returns the source text of the node, including the leading trivia
+// of its first token. The text of a program's tree is its source.
+func (n *SyntaxNode) Text() string {
+	var b strings.Builder
+	for _, tok := range n.Tokens() {
+		for _, trivia := range tok.Leading {
+			b.WriteString(n.source[trivia.Pos.Offset:trivia.End.Offset])
+		}
+		b.WriteString(n.source[tok.Pos.Offset:tok.End.Offset])
+	}
+	return b.String()
+}
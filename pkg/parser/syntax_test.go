@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/ast"
+	"github.com/krizos/php-go/pkg/lexer"
+)
+
+// parseSyntax parses the input with trivia and returns its syntax tree
+func parseSyntax(t *testing.T, input string) (*ast.Program, *SyntaxNode) {
+	t.Helper()
+	l := lexer.New(input, "test.php")
+	l.KeepTrivia()
+	p := New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program, p.SyntaxTree(program)
+}
+
+func TestSyntaxTree_RoundTrip(t *testing.T) {
+	inputs := []string{
+		spanSource,
+		"<?php\r\n\t$a  =  1 ;  # trailing\r\n",
+		"<!DOCTYPE html>\n<ul>\n<?php foreach ($items as $item): ?>\n  <li><?= $item ?></li>\n<?php endforeach; ?>\n</ul>\n",
+		"<?php\necho <<<EOT\n  Hello {$name}\n  EOT;\necho 'a\\'b', \"c\\t$d\";\n/* unterminated",
+	}
+	for _, input := range inputs {
+		l := lexer.New(input, "test.php")
+		l.KeepTrivia()
+		p := New(l)
+		if text := p.SyntaxTree(p.ParseProgram()).Text(); text != input {
+			t.Errorf("Expected the source\n%q\ngot\n%q", input, text)
+		}
+	}
+}
+
+func TestSyntaxTree_Structure(t *testing.T) {
+	program, tree := parseSyntax(t, "<?php\n/** Adds */\nfunction add($a, $b) {\n    return $a + $b; // sum\n}\n")
+
+	if tree.Node != program || len(tree.Children) != 2 || tree.Children[1].Token.Type != lexer.EOF {
+		t.Fatalf("Expected the program with a function and EOF, got %+v", tree.Children)
+	}
+	fn := tree.Children[0].Node
+	decl, ok := fn.Node.(*ast.FunctionDeclaration)
+	if !ok {
+		t.Fatalf("Expected a function declaration, got %T", fn.Node)
+	}
+	if decl.DocComment != "/** Adds */" {
+		t.Errorf("Expected the doc comment, got %q", decl.DocComment)
+	}
+	if text := fn.Text(); text != "<?php\n/** Adds */\nfunction add($a, $b) {\n    return $a + $b; // sum\n}" {
+		t.Errorf("Unexpected function text %q", text)
+	}
+
+	// The return statement owns its keyword and semicolon, the binary
+	// expression its operands and operator
+	var ret *SyntaxNode
+	var find func(n *SyntaxNode)
+	find = func(n *SyntaxNode) {
+		for _, child := range n.Children {
+			if child.Node != nil {
+				if _, ok := child.Node.Node.(*ast.ReturnStatement); ok {
+					ret = child.Node
+				}
+				find(child.Node)
+			}
+		}
+	}
+	find(tree)
+	if ret == nil {
+		t.Fatal("Expected a return statement in the tree")
+	}
+	var kinds []lexer.TokenType
+	for _, child := range ret.Children {
+		if child.Token != nil {
+			kinds = append(kinds, child.Token.Type)
+		}
+	}
+	if len(kinds) != 2 || kinds[0] != lexer.RETURN || kinds[1] != lexer.SEMICOLON {
+		t.Errorf("Expected the return keyword and semicolon, got %v", kinds)
+	}
+	if text := ret.Text(); text != "\n    return $a + $b;" {
+		t.Errorf("Unexpected return text %q", text)
+	}
+}
+
+func TestSyntaxTree_DefaultMode(t *testing.T) {
+	p := New(lexer.New("<?php echo 1;", "test.php"))
+	if tree := p.SyntaxTree(p.ParseProgram()); tree != nil {
+		t.Errorf("Expected no syntax tree without trivia, got %+v", tree)
+	}
+}
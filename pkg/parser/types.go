@@ -7,13 +7,14 @@ import (
 
 // parseType parses a complete type hint including nullable, union, and intersection types
 // This is the main entry point for type parsing
-func (p *Parser) parseType() ast.Expr {
+func (p *Parser) parseType() ast.TypeNode {
 	return p.parseUnionType()
 }
 
 // parseUnionType parses union types (Type1|Type2|Type3)
-// Union has the lowest precedence
-func (p *Parser) parseUnionType() ast.Expr {
+// Union has the lowest precedence; its members may be parenthesized
+// intersections, forming a DNF type: (A&B)|C
+func (p *Parser) parseUnionType() ast.TypeNode {
 	left := p.parseIntersectionType()
 
 	if !p.peekTokenIs(lexer.BITWISE_OR) {
@@ -32,6 +33,12 @@ func (p *Parser) parseUnionType() ast.Expr {
 		union.Types = append(union.Types, p.parseIntersectionType())
 	}
 
+	for _, member := range union.Types {
+		if _, ok := member.(*ast.NullableType); ok {
+			p.error("nullable type " + member.String() + " cannot be part of a union type, use T|null instead")
+		}
+	}
+
 	return union
 }
 
@@ -40,28 +47,43 @@ func (p *Parser) parseUnionType() ast.Expr {
 // Note: In PHP, & in parameter position can mean either:
 //   - Intersection type: Countable&Traversable $x
 //   - By-reference: array &$x
-// We only parse as intersection if & is followed by a type token, not a variable
-func (p *Parser) parseIntersectionType() ast.Expr {
+// & is only parsed as intersection when it is followed by a type rather
+// than by a variable or ...
+func (p *Parser) parseIntersectionType() ast.TypeNode {
 	left := p.parseSingleType()
 
-	// Check if we have potential intersection type
-	if !p.peekTokenIs(lexer.BITWISE_AND) {
+	// Check if we have an intersection type
+	if !p.peekTokenIs(lexer.BITWISE_AND) || !isTypeTokenType(p.peekSecondToken().Type) {
 		return left
 	}
 
-	// Don't parse & as intersection in these cases:
-	// 1. Current context might be a parameter and & could mean by-reference
-	// We need a way to disambiguate, so we only proceed if we're confident
-	// this is an intersection type (i.e., followed by another type token)
+	intersection := &ast.IntersectionType{
+		Token: p.curToken,
+		Types: []ast.Expr{left},
+	}
 
-	// For now, skip intersection type parsing to avoid conflicts with by-reference parameters
-	// Full intersection type support can be added later with proper context tracking
-	return left
+	for p.peekTokenIs(lexer.BITWISE_AND) && isTypeTokenType(p.peekSecondToken().Type) {
+		p.nextToken() // consume &
+		p.nextToken() // move to next type
+		intersection.Types = append(intersection.Types, p.parseBaseType())
+	}
+
+	// Only classes can be intersected
+	for _, member := range intersection.Types {
+		ident, ok := member.(*ast.Identifier)
+		if !ok || isScalarType(ident.Value) || isSpecialType(ident.Value) || isCompoundType(ident.Value) {
+			if member != nil {
+				p.error("type " + member.String() + " cannot be part of an intersection type")
+			}
+		}
+	}
+
+	return intersection
 }
 
 // parseSingleType parses a single type (possibly nullable)
-// Handles: ?Type, Type, (Type)
-func (p *Parser) parseSingleType() ast.Expr {
+// Handles: ?Type, Type, (Type1&Type2)
+func (p *Parser) parseSingleType() ast.TypeNode {
 	// Check for nullable type (?Type)
 	if p.curTokenIs(lexer.QUESTION) {
 		nullableToken := p.curToken
@@ -72,13 +94,16 @@ func (p *Parser) parseSingleType() ast.Expr {
 		}
 	}
 
-	// Check for parenthesized type
+	// Check for a parenthesized intersection of a DNF type
 	if p.curTokenIs(lexer.LPAREN) {
 		p.nextToken() // consume (
-		typeExpr := p.parseType()
+		typeExpr := p.parseIntersectionType()
 		if !p.expectPeek(lexer.RPAREN) {
 			return nil
 		}
+		if _, ok := typeExpr.(*ast.IntersectionType); !ok && typeExpr != nil {
+			p.error("only intersection types can be parenthesized, got (" + typeExpr.String() + ")")
+		}
 		return typeExpr
 	}
 
@@ -88,7 +113,7 @@ func (p *Parser) parseSingleType() ast.Expr {
 // parseBaseType parses a base type (identifier, scalar, special)
 // This includes: int, string, bool, float, array, callable, iterable, object
 // mixed, never, void, static, self, parent, ClassName
-func (p *Parser) parseBaseType() ast.Expr {
+func (p *Parser) parseBaseType() ast.TypeNode {
 	// Check if it's a type keyword or identifier
 	if !p.isTypeToken() {
 		p.error("expected type name")
@@ -109,7 +134,12 @@ func (p *Parser) parseBaseType() ast.Expr {
 
 // isTypeToken checks if the current token can be used as a type
 func (p *Parser) isTypeToken() bool {
-	switch p.curToken.Type {
+	return isTypeTokenType(p.curToken.Type)
+}
+
+// isTypeTokenType checks if a token of the given type can be used as a type
+func isTypeTokenType(t lexer.TokenType) bool {
+	switch t {
 	// Scalar type keywords
 	case lexer.INT, lexer.FLOAT_TYPE, lexer.BOOL, lexer.STRING_TYPE:
 		return true
@@ -218,6 +248,6 @@ func (p *Parser) parseTypeList() []ast.Expr {
 
 // updateParseTypeHint updates the old parseTypeHint function to use the new type parser
 // This is called from decl.go for backward compatibility
-func (p *Parser) parseTypeHintCompat() ast.Expr {
+func (p *Parser) parseTypeHintCompat() ast.TypeNode {
 	return p.parseType()
 }
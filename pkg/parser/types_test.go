@@ -353,8 +353,6 @@ func TestUnionReturnType(t *testing.T) {
 // This will be re-enabled with proper context tracking
 
 func TestIntersectionType(t *testing.T) {
-	t.Skip("Intersection type parsing temporarily disabled - conflicts with by-reference syntax")
-
 	input := `<?php function test(Countable&Traversable $x) {}`
 
 	l := lexer.New(input, "test.php")
@@ -388,8 +386,6 @@ func TestIntersectionType(t *testing.T) {
 }
 
 func TestIntersectionTypeMultiple(t *testing.T) {
-	t.Skip("Intersection type parsing temporarily disabled - conflicts with by-reference syntax")
-
 	input := `<?php function test(A&B&C $x) {}`
 
 	l := lexer.New(input, "test.php")
@@ -418,6 +414,62 @@ func TestIntersectionTypeMultiple(t *testing.T) {
 	}
 }
 
+func TestDNFType(t *testing.T) {
+	input := `<?php function test((A&B)|null $x): (Countable&Iterator)|array {}`
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	funcDecl := program.Statements[0].(*ast.FunctionDeclaration)
+	unionType, ok := funcDecl.Parameters[0].Type.(*ast.UnionType)
+	if !ok {
+		t.Fatalf("type is not *ast.UnionType. got=%T", funcDecl.Parameters[0].Type)
+	}
+	if _, ok := unionType.Types[0].(*ast.IntersectionType); !ok || len(unionType.Types) != 2 {
+		t.Errorf("expected an intersection and null. got=%s", unionType.String())
+	}
+
+	if funcDecl.ReturnType.String() != "(Countable&Iterator)|array" {
+		t.Errorf("return type not '(Countable&Iterator)|array'. got=%s", funcDecl.ReturnType.String())
+	}
+}
+
+func TestByRefParameterAfterType(t *testing.T) {
+	input := `<?php function test(array &$x, Foo & $y) {}`
+
+	l := lexer.New(input, "test.php")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	funcDecl := program.Statements[0].(*ast.FunctionDeclaration)
+	for i, name := range []string{"array", "Foo"} {
+		param := funcDecl.Parameters[i]
+		if !param.ByRef || param.Type.String() != name {
+			t.Errorf("parameter %d: expected by-ref %s. got=%s (by-ref %v)", i, name, param.Type.String(), param.ByRef)
+		}
+	}
+}
+
+func TestInvalidCompositeTypes(t *testing.T) {
+	tests := []string{
+		`<?php function test(?int|string $x) {}`,
+		`<?php function test(int&string $x) {}`,
+		`<?php function test((A|B)&C $x) {}`,
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input, "test.php")
+		p := New(l)
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected a parse error for %s", input)
+		}
+	}
+}
+
 // Test class/interface type names
 
 func TestClassTypeName(t *testing.T) {
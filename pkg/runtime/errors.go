@@ -23,6 +23,26 @@ const (
 	E_ALL               ErrorType = 32767  // All errors and warnings
 )
 
+// ErrorConstants maps the names of the E_* constants to their levels
+var ErrorConstants = map[string]ErrorType{
+	"E_ERROR":             E_ERROR,
+	"E_WARNING":           E_WARNING,
+	"E_PARSE":             E_PARSE,
+	"E_NOTICE":            E_NOTICE,
+	"E_CORE_ERROR":        E_CORE_ERROR,
+	"E_CORE_WARNING":      E_CORE_WARNING,
+	"E_COMPILE_ERROR":     E_COMPILE_ERROR,
+	"E_COMPILE_WARNING":   E_COMPILE_WARNING,
+	"E_USER_ERROR":        E_USER_ERROR,
+	"E_USER_WARNING":      E_USER_WARNING,
+	"E_USER_NOTICE":       E_USER_NOTICE,
+	"E_STRICT":            E_STRICT,
+	"E_RECOVERABLE_ERROR": E_RECOVERABLE_ERROR,
+	"E_DEPRECATED":        E_DEPRECATED,
+	"E_USER_DEPRECATED":   E_USER_DEPRECATED,
+	"E_ALL":               E_ALL,
+}
+
 // String returns the string representation of an error type
 func (et ErrorType) String() string {
 	switch et {
@@ -0,0 +1,443 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// Directives
+// ============================================================================
+
+// Changeability of ini directives: where a directive can be set
+const (
+	INI_USER   = 1 // ini_set() in scripts
+	INI_PERDIR = 2 // Per-directory configuration
+	INI_SYSTEM = 4 // The configuration file and -d flags
+	INI_ALL    = INI_USER | INI_PERDIR | INI_SYSTEM
+)
+
+// IniDirective is a configuration setting with its default value and
+// where it can be changed
+type IniDirective struct {
+	Name    string
+	Default string
+	Access  int
+}
+
+// DefaultDirectives are the directives of every configuration, with the
+// defaults of the CLI
+var DefaultDirectives = []IniDirective{
+	{"allow_url_fopen", "1", INI_SYSTEM},
+	{"auto_prepend_file", "", INI_PERDIR | INI_SYSTEM},
+	{"bcmath.scale", "0", INI_ALL},
+	{"date.timezone", "", INI_ALL},
+	{"default_charset", "UTF-8", INI_ALL},
+	{"default_socket_timeout", "60", INI_ALL},
+	{"display_errors", "1", INI_ALL},
+	{"display_startup_errors", "1", INI_ALL},
+	{"error_log", "", INI_ALL},
+	{"error_reporting", strconv.Itoa(int(E_ALL)), INI_ALL},
+	{"file_uploads", "1", INI_SYSTEM},
+	{"html_errors", "0", INI_ALL},
+	{"implicit_flush", "1", INI_ALL},
+	{"include_path", ".", INI_ALL},
+	{"log_errors", "0", INI_ALL},
+	{"max_execution_time", "0", INI_ALL},
+	{"max_input_time", "-1", INI_PERDIR | INI_SYSTEM},
+	{"memory_limit", "128M", INI_ALL},
+	{"output_buffering", "0", INI_PERDIR | INI_SYSTEM},
+	{"post_max_size", "8M", INI_PERDIR | INI_SYSTEM},
+	{"precision", "14", INI_ALL},
+	{"register_argc_argv", "1", INI_PERDIR | INI_SYSTEM},
+	{"serialize_precision", "-1", INI_ALL},
+	{"session.cookie_domain", "", INI_ALL},
+	{"session.cookie_httponly", "0", INI_ALL},
+	{"session.cookie_lifetime", "0", INI_ALL},
+	{"session.cookie_path", "/", INI_ALL},
+	{"session.cookie_samesite", "", INI_ALL},
+	{"session.cookie_secure", "0", INI_ALL},
+	{"session.gc_divisor", "100", INI_ALL},
+	{"session.gc_maxlifetime", "1440", INI_ALL},
+	{"session.gc_probability", "1", INI_ALL},
+	{"session.lazy_write", "1", INI_ALL},
+	{"session.name", "PHPSESSID", INI_ALL},
+	{"session.save_path", "", INI_ALL},
+	{"session.use_cookies", "1", INI_ALL},
+	{"short_open_tag", "1", INI_PERDIR | INI_SYSTEM},
+	{"upload_max_filesize", "2M", INI_PERDIR | INI_SYSTEM},
+	{"user_agent", "", INI_ALL},
+	{"variables_order", "EGPCS", INI_PERDIR | INI_SYSTEM},
+	{"xdebug.max_nesting_level", "1000", INI_ALL},
+	{"zend.enable_gc", "1", INI_ALL},
+}
+
+// Errors of Config.Set that ini_set() reports by returning false
+var (
+	ErrUnknownDirective = errors.New("unknown directive")
+	ErrNotChangeable    = errors.New("directive cannot be changed at this level")
+)
+
+// iniEntry is a registered directive with its values: the global one of
+// the configuration file and the local one the script changed
+type iniEntry struct {
+	IniDirective
+	global   string
+	local    string
+	onChange func(value string) error
+}
+
+// IniSetting describes a directive, as ini_get_all() does
+type IniSetting struct {
+	Name        string
+	GlobalValue string
+	LocalValue  string
+	Access      int
+}
+
+// Config is the ini configuration of a script: the registered directives
+// and the values the configuration file sets
+type Config struct {
+	entries    map[string]*iniEntry
+	unknown    map[string]string // File values of unregistered directives
+	loadedFile string
+}
+
+// NewConfig returns a configuration of the default directives
+func NewConfig() *Config {
+	c := &Config{entries: make(map[string]*iniEntry), unknown: make(map[string]string)}
+	for _, d := range DefaultDirectives {
+		c.Register(d)
+	}
+	return c
+}
+
+// Register adds a directive; a value the configuration file set for it
+// before replaces its default
+func (c *Config) Register(d IniDirective) {
+	value := d.Default
+	if v, ok := c.unknown[d.Name]; ok {
+		value = v
+		delete(c.unknown, d.Name)
+	}
+	c.entries[d.Name] = &iniEntry{IniDirective: d, global: value, local: value}
+}
+
+// OnChange sets the function applying the values of a directive and
+// applies its current value. The function rejects invalid values with an
+// error.
+func (c *Config) OnChange(name string, apply func(value string) error) {
+	entry, ok := c.entries[name]
+	if !ok {
+		return
+	}
+	entry.onChange = apply
+	apply(entry.local)
+}
+
+// Get returns the current value of a directive
+func (c *Config) Get(name string) (string, bool) {
+	entry, ok := c.entries[name]
+	if !ok {
+		return "", false
+	}
+	return entry.local, true
+}
+
+// GlobalValue returns the value the configuration file set, or the
+// default, as get_cfg_var() does; unregistered directives of the file
+// are included
+func (c *Config) GlobalValue(name string) (string, bool) {
+	if entry, ok := c.entries[name]; ok {
+		return entry.global, true
+	}
+	value, ok := c.unknown[name]
+	return value, ok
+}
+
+// Set changes a directive where access allows it, returning the previous
+// value. Changes at INI_SYSTEM level, from the configuration file and -d
+// flags, also set the global value and keep unregistered directives.
+func (c *Config) Set(name, value string, access int) (string, error) {
+	entry, ok := c.entries[name]
+	if !ok {
+		if access&INI_SYSTEM != 0 {
+			c.unknown[name] = value
+			return "", nil
+		}
+		return "", fmt.Errorf("%w %q", ErrUnknownDirective, name)
+	}
+	if entry.Access&access == 0 {
+		return "", fmt.Errorf("%s: %w", name, ErrNotChangeable)
+	}
+	if entry.onChange != nil {
+		if err := entry.onChange(value); err != nil {
+			return "", err
+		}
+	}
+	old := entry.local
+	entry.local = value
+	if access&INI_SYSTEM != 0 {
+		entry.global = value
+	}
+	return old, nil
+}
+
+// Restore sets a directive back to its global value
+func (c *Config) Restore(name string) {
+	if entry, ok := c.entries[name]; ok && entry.local != entry.global {
+		if entry.onChange != nil {
+			entry.onChange(entry.global)
+		}
+		entry.local = entry.global
+	}
+}
+
+// All returns the registered directives sorted by name
+func (c *Config) All() []IniSetting {
+	settings := make([]IniSetting, 0, len(c.entries))
+	for _, entry := range c.entries {
+		settings = append(settings, IniSetting{entry.Name, entry.global, entry.local, entry.Access})
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Name < settings[j].Name })
+	return settings
+}
+
+// LoadedFile returns the path of the configuration file loaded, if any
+func (c *Config) LoadedFile() string {
+	return c.loadedFile
+}
+
+// LoadFile applies the directives of a configuration file
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	pairs, err := ParseIni(string(data), path)
+	if err != nil {
+		return err
+	}
+	for _, pair := range pairs {
+		if _, err := c.Set(pair[0], pair[1], INI_SYSTEM); err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	c.loadedFile = path
+	return nil
+}
+
+// ============================================================================
+// Values
+// ============================================================================
+
+// IniBool interprets a directive value as a boolean: "1", "on", "yes" and
+// "true" are true, as are other non-zero numbers
+func IniBool(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "on", "yes", "true":
+		return true
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(value))
+	return n != 0
+}
+
+// ParseQuantity parses a size such as memory_limit's "128M": a number
+// with an optional K, M or G suffix. -1 means no limit.
+func ParseQuantity(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	multiplier := int64(1)
+	switch value[len(value)-1] {
+	case 'k', 'K':
+		multiplier = 1 << 10
+	case 'm', 'M':
+		multiplier = 1 << 20
+	case 'g', 'G':
+		multiplier = 1 << 30
+	}
+	digits := value
+	if multiplier != 1 {
+		digits = value[:len(value)-1]
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(digits), 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid quantity %q", value)
+	}
+	return n * multiplier, nil
+}
+
+// ============================================================================
+// Parsing
+// ============================================================================
+
+// iniVariable matches the ${NAME} references to environment variables
+var iniVariable = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// ParseIni parses the "name = value" lines of an ini file into name and
+// value pairs. Sections and ";" comments are skipped. Unquoted values may
+// be the keywords on/off/yes/no/true/false/none/null, constants or bitwise
+// expressions of them, such as E_ALL & ~E_DEPRECATED; ${NAME} is replaced
+// by the environment variable.
+func ParseIni(data, filename string) ([][2]string, error) {
+	var pairs [][2]string
+	for n, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == ';' || line[0] == '[' && strings.HasSuffix(line, "]") {
+			continue
+		}
+		name, raw, _ := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("syntax error, unexpected '=' in %s on line %d", filename, n+1)
+		}
+		value, err := iniValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%s in %s on line %d", err, filename, n+1)
+		}
+		pairs = append(pairs, [2]string{name, value})
+	}
+	return pairs, nil
+}
+
+// iniValue interprets the raw value of a line
+func iniValue(raw string) (string, error) {
+	expand := func(s string) string {
+		return iniVariable.ReplaceAllStringFunc(s, func(ref string) string {
+			return os.Getenv(ref[2 : len(ref)-1])
+		})
+	}
+	if raw == "" {
+		return "", nil
+	}
+	switch raw[0] {
+	case '"':
+		var b strings.Builder
+		for i := 1; i < len(raw); i++ {
+			switch raw[i] {
+			case '\\':
+				if i+1 < len(raw) && (raw[i+1] == '"' || raw[i+1] == '\\') {
+					i++
+				}
+			case '"':
+				return expand(b.String()), nil
+			}
+			b.WriteByte(raw[i])
+		}
+		return "", fmt.Errorf("syntax error, unexpected end of line, expecting '\"'")
+	case '\'':
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("syntax error, unexpected end of line, expecting \"'\"")
+		}
+		return raw[1 : end+1], nil
+	}
+
+	if i := strings.IndexByte(raw, ';'); i >= 0 {
+		raw = strings.TrimSpace(raw[:i])
+	}
+	switch strings.ToLower(raw) {
+	case "on", "yes", "true":
+		return "1", nil
+	case "off", "no", "false", "none", "null":
+		return "", nil
+	}
+	if value, ok := iniExpression(raw); ok {
+		return value, nil
+	}
+	return expand(raw), nil
+}
+
+// iniToken splits the bitwise expressions of ini values
+var iniToken = regexp.MustCompile(`\s*([A-Za-z_][A-Za-z0-9_]*|-?[0-9]+|[|&^~!()])`)
+
+// iniExpression evaluates a value made of constants, integers and the
+// operators | & ^ ~ ! and parentheses. Binary operators have the same
+// precedence and associate to the left, as in php.ini. A lone constant
+// gives its value as a string.
+func iniExpression(raw string) (string, bool) {
+	var tokens []string
+	for rest := raw; strings.TrimSpace(rest) != ""; {
+		m := iniToken.FindStringSubmatchIndex(rest)
+		if m == nil || m[0] != 0 {
+			return "", false
+		}
+		tokens = append(tokens, rest[m[2]:m[3]])
+		rest = rest[m[1]:]
+	}
+	constants := BuiltinConstants()
+	if len(tokens) == 1 {
+		if value, ok := constants[tokens[0]]; ok {
+			return value.ToString(), true
+		}
+		return "", false
+	}
+
+	pos := 0
+	var operand func() (int64, bool)
+	var expr func() (int64, bool)
+	operand = func() (int64, bool) {
+		if pos >= len(tokens) {
+			return 0, false
+		}
+		token := tokens[pos]
+		pos++
+		switch token {
+		case "~":
+			v, ok := operand()
+			return ^v, ok
+		case "!":
+			v, ok := operand()
+			if v == 0 {
+				return 1, ok
+			}
+			return 0, ok
+		case "(":
+			v, ok := expr()
+			if !ok || pos >= len(tokens) || tokens[pos] != ")" {
+				return 0, false
+			}
+			pos++
+			return v, true
+		}
+		if value, ok := constants[token]; ok {
+			return value.ToInt(), true
+		}
+		n, err := strconv.ParseInt(token, 10, 64)
+		return n, err == nil
+	}
+	expr = func() (int64, bool) {
+		v, ok := operand()
+		for ok && pos < len(tokens) && strings.Contains("|&^", tokens[pos]) {
+			op := tokens[pos]
+			pos++
+			var rhs int64
+			rhs, ok = operand()
+			switch op {
+			case "|":
+				v |= rhs
+			case "&":
+				v &= rhs
+			case "^":
+				v ^= rhs
+			}
+		}
+		return v, ok
+	}
+	v, ok := expr()
+	if !ok || pos != len(tokens) {
+		return "", false
+	}
+	return strconv.FormatInt(v, 10), true
+}
@@ -0,0 +1,109 @@
+package runtime
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_SetAndRestore(t *testing.T) {
+	c := NewConfig()
+
+	if v, ok := c.Get("memory_limit"); !ok || v != "128M" {
+		t.Errorf("memory_limit = %q, %v", v, ok)
+	}
+	old, err := c.Set("memory_limit", "256M", INI_USER)
+	if err != nil || old != "128M" {
+		t.Fatalf("Set() = %q, %v", old, err)
+	}
+	if v, _ := c.Get("memory_limit"); v != "256M" {
+		t.Errorf("memory_limit after Set() = %q", v)
+	}
+	if v, _ := c.GlobalValue("memory_limit"); v != "128M" {
+		t.Errorf("global memory_limit = %q", v)
+	}
+	c.Restore("memory_limit")
+	if v, _ := c.Get("memory_limit"); v != "128M" {
+		t.Errorf("memory_limit after Restore() = %q", v)
+	}
+
+	if _, err := c.Set("variables_order", "GP", INI_USER); !errors.Is(err, ErrNotChangeable) {
+		t.Errorf("Set(variables_order) error = %v", err)
+	}
+	if _, err := c.Set("no.such", "1", INI_USER); !errors.Is(err, ErrUnknownDirective) {
+		t.Errorf("Set(no.such) error = %v", err)
+	}
+}
+
+func TestConfig_OnChange(t *testing.T) {
+	c := NewConfig()
+	var applied []string
+	c.OnChange("precision", func(value string) error {
+		if value == "bad" {
+			return errors.New("rejected")
+		}
+		applied = append(applied, value)
+		return nil
+	})
+	c.Set("precision", "10", INI_USER)
+	if _, err := c.Set("precision", "bad", INI_USER); err == nil {
+		t.Error("Set() accepted a rejected value")
+	}
+	if v, _ := c.Get("precision"); v != "10" {
+		t.Errorf("precision = %q", v)
+	}
+	if len(applied) != 2 || applied[0] != "14" || applied[1] != "10" {
+		t.Errorf("applied = %v", applied)
+	}
+}
+
+func TestConfig_LoadFile(t *testing.T) {
+	t.Setenv("PHPGO_INI_TEST", "/tmp/sessions")
+	path := filepath.Join(t.TempDir(), "php-go.ini")
+	data := `; comment
+[PHP]
+memory_limit = 64M
+display_errors = Off
+error_reporting = E_ALL & ~E_DEPRECATED
+session.save_path = "${PHPGO_INI_TEST}"
+custom.setting = yes
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := NewConfig()
+	if err := c.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	expected := map[string]string{
+		"memory_limit":      "64M",
+		"display_errors":    "",
+		"error_reporting":   "24575",
+		"session.save_path": "/tmp/sessions",
+	}
+	for name, want := range expected {
+		if v, _ := c.Get(name); v != want {
+			t.Errorf("%s = %q, want %q", name, v, want)
+		}
+	}
+	if v, ok := c.GlobalValue("custom.setting"); !ok || v != "1" {
+		t.Errorf("custom.setting = %q, %v", v, ok)
+	}
+	if c.LoadedFile() != path {
+		t.Errorf("LoadedFile() = %q", c.LoadedFile())
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := map[string]int64{"128M": 128 << 20, "1g": 1 << 30, "512K": 512 << 10, "1000": 1000, "-1": -1}
+	for input, want := range tests {
+		if got, err := ParseQuantity(input); err != nil || got != want {
+			t.Errorf("ParseQuantity(%q) = %d, %v", input, got, err)
+		}
+	}
+	if _, err := ParseQuantity("lots"); err == nil {
+		t.Error("ParseQuantity(lots) accepted")
+	}
+}
@@ -5,6 +5,17 @@ import (
 	"os"
 	"time"
 
+	stdarray "github.com/krizos/php-go/pkg/stdlib/array"
+	"github.com/krizos/php-go/pkg/stdlib/curl"
+	"github.com/krizos/php-go/pkg/stdlib/datetime"
+	stdfile "github.com/krizos/php-go/pkg/stdlib/file"
+	"github.com/krizos/php-go/pkg/stdlib/filter"
+	stdhash "github.com/krizos/php-go/pkg/stdlib/hash"
+	stdjson "github.com/krizos/php-go/pkg/stdlib/json"
+	stdmath "github.com/krizos/php-go/pkg/stdlib/math"
+	"github.com/krizos/php-go/pkg/stdlib/mbstring"
+	"github.com/krizos/php-go/pkg/stdlib/pcre"
+	"github.com/krizos/php-go/pkg/stdlib/session"
 	"github.com/krizos/php-go/pkg/types"
 )
 
@@ -96,26 +107,127 @@ func (rt *Runtime) ConstantExists(name string) bool {
 
 // initBuiltinConstants initializes PHP built-in constants
 func (rt *Runtime) initBuiltinConstants() {
+	for name, value := range BuiltinConstants() {
+		rt.constants[name] = value
+	}
+}
+
+// BuiltinConstants returns the constants PHP defines before a script runs
+func BuiltinConstants() map[string]*types.Value {
+	constants := make(map[string]*types.Value)
+
 	// PHP version constants
-	rt.constants["PHP_VERSION"] = types.NewString("8.4.0-dev")
-	rt.constants["PHP_MAJOR_VERSION"] = types.NewInt(8)
-	rt.constants["PHP_MINOR_VERSION"] = types.NewInt(4)
-	rt.constants["PHP_RELEASE_VERSION"] = types.NewInt(0)
+	constants["PHP_VERSION"] = types.NewString("8.4.0-dev")
+	constants["PHP_MAJOR_VERSION"] = types.NewInt(8)
+	constants["PHP_MINOR_VERSION"] = types.NewInt(4)
+	constants["PHP_RELEASE_VERSION"] = types.NewInt(0)
 
 	// Boolean constants
-	rt.constants["TRUE"] = types.NewBool(true)
-	rt.constants["FALSE"] = types.NewBool(false)
-	rt.constants["NULL"] = types.NewNull()
+	constants["TRUE"] = types.NewBool(true)
+	constants["FALSE"] = types.NewBool(false)
+	constants["NULL"] = types.NewNull()
 
 	// Path constants (will be updated when script runs)
-	rt.constants["PHP_EOL"] = types.NewString("\n")
-	rt.constants["DIRECTORY_SEPARATOR"] = types.NewString(string(os.PathSeparator))
-
-	// Math constants
-	rt.constants["PHP_INT_MAX"] = types.NewInt(9223372036854775807)
-	rt.constants["PHP_INT_MIN"] = types.NewInt(-9223372036854775808)
-	rt.constants["PHP_FLOAT_MAX"] = types.NewFloat(1.7976931348623157e+308)
-	rt.constants["PHP_FLOAT_MIN"] = types.NewFloat(2.2250738585072014e-308)
+	constants["PHP_EOL"] = types.NewString("\n")
+	constants["DIRECTORY_SEPARATOR"] = types.NewString(string(os.PathSeparator))
+
+	// Error level constants (E_ALL, E_WARNING, ...)
+	for name, level := range ErrorConstants {
+		constants[name] = types.NewInt(int64(level))
+	}
+
+	// ini changeability constants (INI_USER, INI_ALL, ...)
+	for name, value := range map[string]int64{
+		"INI_USER":   INI_USER,
+		"INI_PERDIR": INI_PERDIR,
+		"INI_SYSTEM": INI_SYSTEM,
+		"INI_ALL":    INI_ALL,
+	} {
+		constants[name] = types.NewInt(value)
+	}
+
+	// Output control constants (phases and flags of ob_start() handlers)
+	for name, value := range map[string]int64{
+		"PHP_OUTPUT_HANDLER_START":     1,
+		"PHP_OUTPUT_HANDLER_WRITE":     0,
+		"PHP_OUTPUT_HANDLER_FLUSH":     4,
+		"PHP_OUTPUT_HANDLER_CLEAN":     2,
+		"PHP_OUTPUT_HANDLER_FINAL":     8,
+		"PHP_OUTPUT_HANDLER_CONT":      0,
+		"PHP_OUTPUT_HANDLER_END":       8,
+		"PHP_OUTPUT_HANDLER_CLEANABLE": 16,
+		"PHP_OUTPUT_HANDLER_FLUSHABLE": 32,
+		"PHP_OUTPUT_HANDLER_REMOVABLE": 64,
+		"PHP_OUTPUT_HANDLER_STDFLAGS":  112,
+	} {
+		constants[name] = types.NewInt(value)
+	}
+
+	// Code coverage options of xdebug_start_code_coverage()
+	for name, value := range map[string]int64{
+		"XDEBUG_CC_UNUSED":       1,
+		"XDEBUG_CC_DEAD_CODE":    2,
+		"XDEBUG_CC_BRANCH_CHECK": 4,
+	} {
+		constants[name] = types.NewInt(value)
+	}
+
+	// Math constants (M_PI, PHP_INT_MAX, PHP_ROUND_HALF_UP, ...)
+	for name, value := range stdmath.Constants() {
+		constants[name] = value
+	}
+
+	// Sort flags (SORT_REGULAR, SORT_NATURAL, SORT_DESC, ...)
+	for name, value := range stdarray.Constants() {
+		constants[name] = value
+	}
+
+	// Hash and password constants (HASH_HMAC, PASSWORD_DEFAULT, ...)
+	for name, value := range stdhash.Constants() {
+		constants[name] = value
+	}
+
+	// PCRE constants (PREG_SPLIT_NO_EMPTY, PREG_OFFSET_CAPTURE, ...)
+	for name, value := range pcre.Constants() {
+		constants[name] = value
+	}
+
+	// JSON constants (JSON_PRETTY_PRINT, JSON_THROW_ON_ERROR, ...)
+	for name, value := range stdjson.Constants() {
+		constants[name] = value
+	}
+
+	// mbstring case modes (MB_CASE_UPPER, MB_CASE_TITLE, ...)
+	for name, value := range mbstring.Constants() {
+		constants[name] = value
+	}
+
+	// Date formats (DATE_ATOM, DATE_RFC2822, ...)
+	for name, value := range datetime.Constants() {
+		constants[name] = value
+	}
+
+	// Filter constants (FILTER_VALIDATE_INT, INPUT_GET, ...)
+	for name, value := range filter.Constants() {
+		constants[name] = value
+	}
+
+	// Session constants (PHP_SESSION_NONE, PHP_SESSION_ACTIVE, ...)
+	for name, value := range session.Constants() {
+		constants[name] = value
+	}
+
+	// File, stream and socket constants (SEEK_END, LOCK_EX, FILE_APPEND, STREAM_SERVER_LISTEN, ...)
+	for name, value := range stdfile.Constants() {
+		constants[name] = value
+	}
+
+	// curl constants (CURLOPT_URL, CURLINFO_HTTP_CODE, CURLE_OK, ...)
+	for name, value := range curl.Constants() {
+		constants[name] = value
+	}
+
+	return constants
 }
 
 // ============================================================================
@@ -106,6 +106,24 @@ func TestBuiltinConstants(t *testing.T) {
 		{"NULL", types.TypeNull},
 		{"PHP_INT_MAX", types.TypeInt},
 		{"PHP_FLOAT_MAX", types.TypeFloat},
+		{"M_PI", types.TypeFloat},
+		{"PHP_ROUND_HALF_EVEN", types.TypeInt},
+		{"MT_RAND_MT19937", types.TypeInt},
+		{"PASSWORD_DEFAULT", types.TypeString},
+		{"HASH_HMAC", types.TypeInt},
+		{"E_ALL", types.TypeInt},
+		{"E_USER_DEPRECATED", types.TypeInt},
+		{"PREG_SPLIT_NO_EMPTY", types.TypeInt},
+		{"PREG_OFFSET_CAPTURE", types.TypeInt},
+		{"PCRE_VERSION", types.TypeString},
+		{"JSON_PRETTY_PRINT", types.TypeInt},
+		{"JSON_ERROR_SYNTAX", types.TypeInt},
+		{"MB_CASE_TITLE", types.TypeInt},
+		{"DATE_ATOM", types.TypeString},
+		{"DATE_RFC2822", types.TypeString},
+		{"SEEK_END", types.TypeInt},
+		{"LOCK_EX", types.TypeInt},
+		{"FILE_APPEND", types.TypeInt},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,191 @@
+// Package fastcgi serves PHP programs over the FastCGI protocol, so php-go
+// can run behind nginx or Apache the way PHP-FPM does.
+package fastcgi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ============================================================================
+// Records
+// ============================================================================
+
+// recordType is the type of a FastCGI record
+type recordType uint8
+
+// Record types of the FastCGI specification
+const (
+	typeBeginRequest    recordType = 1
+	typeAbortRequest    recordType = 2
+	typeEndRequest      recordType = 3
+	typeParams          recordType = 4
+	typeStdin           recordType = 5
+	typeStdout          recordType = 6
+	typeStderr          recordType = 7
+	typeData            recordType = 8
+	typeGetValues       recordType = 9
+	typeGetValuesResult recordType = 10
+	typeUnknownType     recordType = 11
+)
+
+// Roles of a BEGIN_REQUEST record; php-go only acts as a responder
+const (
+	roleResponder  = 1
+	roleAuthorizer = 2
+	roleFilter     = 3
+)
+
+// flagKeepConn in a BEGIN_REQUEST asks to keep the connection open after
+// the request
+const flagKeepConn = 1
+
+// Protocol statuses of an END_REQUEST record
+const (
+	statusRequestComplete = 0
+	statusCantMultiplex   = 1
+	statusOverloaded      = 2
+	statusUnknownRole     = 3
+)
+
+const (
+	protocolVersion = 1
+	headerLength    = 8
+	maxContent      = 65535
+)
+
+// record is a FastCGI record: a header naming the type and request, and
+// up to 64K of content
+type record struct {
+	typ     recordType
+	id      uint16
+	content []byte
+}
+
+// readRecord reads the next record of a connection, skipping its padding
+func readRecord(r io.Reader) (*record, error) {
+	var header [headerLength]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if header[0] != protocolVersion {
+		return nil, fmt.Errorf("fastcgi: unsupported protocol version %d", header[0])
+	}
+	length := int(binary.BigEndian.Uint16(header[4:6]))
+	padding := int(header[6])
+
+	body := make([]byte, length+padding)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return &record{
+		typ:     recordType(header[1]),
+		id:      binary.BigEndian.Uint16(header[2:4]),
+		content: body[:length],
+	}, nil
+}
+
+// writeRecord writes a record, padding its content to a multiple of 8
+// bytes as the specification recommends
+func writeRecord(w io.Writer, typ recordType, id uint16, content []byte) error {
+	if len(content) > maxContent {
+		return errors.New("fastcgi: record content too long")
+	}
+	padding := -len(content) & 7
+	buf := make([]byte, headerLength+len(content)+padding)
+	buf[0] = protocolVersion
+	buf[1] = byte(typ)
+	binary.BigEndian.PutUint16(buf[2:4], id)
+	binary.BigEndian.PutUint16(buf[4:6], uint16(len(content)))
+	buf[6] = byte(padding)
+	copy(buf[headerLength:], content)
+	_, err := w.Write(buf)
+	return err
+}
+
+// writeStream writes data as the records of a stream (STDOUT or STDERR),
+// split in chunks a record can hold. The empty record closing the stream
+// is written separately.
+func writeStream(w io.Writer, typ recordType, id uint16, data []byte) error {
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > maxContent {
+			chunk = chunk[:maxContent]
+		}
+		if err := writeRecord(w, typ, id, chunk); err != nil {
+			return err
+		}
+		data = data[len(chunk):]
+	}
+	return nil
+}
+
+// writeEndRequest ends a request with the exit status of the application
+// and a protocol status
+func writeEndRequest(w io.Writer, id uint16, appStatus uint32, protocolStatus uint8) error {
+	var content [8]byte
+	binary.BigEndian.PutUint32(content[:4], appStatus)
+	content[4] = protocolStatus
+	return writeRecord(w, typeEndRequest, id, content[:])
+}
+
+// ============================================================================
+// Name-Value Pairs
+// ============================================================================
+
+// readPairs decodes the name-value pairs of PARAMS and GET_VALUES records.
+// Lengths under 128 take one byte, longer ones four with the high bit set.
+func readPairs(data []byte, pairs map[string]string) error {
+	for len(data) > 0 {
+		nameLength, n := readLength(data)
+		if n == 0 {
+			return errors.New("fastcgi: malformed name-value pair")
+		}
+		data = data[n:]
+		valueLength, n := readLength(data)
+		if n == 0 {
+			return errors.New("fastcgi: malformed name-value pair")
+		}
+		data = data[n:]
+		if uint64(nameLength)+uint64(valueLength) > uint64(len(data)) {
+			return errors.New("fastcgi: name-value pair longer than its record")
+		}
+		name := string(data[:nameLength])
+		pairs[name] = string(data[nameLength : nameLength+valueLength])
+		data = data[nameLength+valueLength:]
+	}
+	return nil
+}
+
+// readLength decodes a pair length, returning the bytes it took or 0 if
+// the data is too short
+func readLength(data []byte) (uint32, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	if data[0]&0x80 == 0 {
+		return uint32(data[0]), 1
+	}
+	if len(data) < 4 {
+		return 0, 0
+	}
+	return binary.BigEndian.Uint32(data[:4]) & 0x7fffffff, 4
+}
+
+// appendPair encodes a name-value pair
+func appendPair(buf []byte, name, value string) []byte {
+	buf = appendLength(buf, len(name))
+	buf = appendLength(buf, len(value))
+	buf = append(buf, name...)
+	return append(buf, value...)
+}
+
+// appendLength encodes a pair length
+func appendLength(buf []byte, length int) []byte {
+	if length < 0x80 {
+		return append(buf, byte(length))
+	}
+	return binary.BigEndian.AppendUint32(buf, uint32(length)|0x80000000)
+}
@@ -0,0 +1,412 @@
+package fastcgi
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/krizos/php-go/pkg/sapi"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// DefaultMaxChildren is the number of requests a server runs at once when
+// NewServer is given no limit
+const DefaultMaxChildren = 5
+
+// ErrServerClosed is returned by Serve after Shutdown
+var ErrServerClosed = errors.New("fastcgi: server closed")
+
+// Server answers FastCGI requests by running the script each names in
+// SCRIPT_FILENAME. Scripts are compiled once and shared by the requests
+// running them, until they change on disk or the server reloads.
+//
+// Like the children of a PHP-FPM pool, at most MaxChildren requests run at
+// once; the others wait for a free worker.
+type Server struct {
+	compile vm.ScriptCompiler
+	workers chan struct{}
+	logger  *log.Logger
+
+	mu        sync.Mutex
+	configure func(*vm.VM)
+	programs  map[string]*cachedProgram
+	reloads   int // Reloads so far, to drop the programs compiled before one
+	listeners map[net.Listener]struct{}
+	conns     map[*conn]bool // Connections, true while serving a request
+	closing   bool
+	wg        sync.WaitGroup
+}
+
+// cachedProgram is a compiled script with the file state it was compiled
+// from
+type cachedProgram struct {
+	program *vm.Program
+	modTime time.Time
+	size    int64
+}
+
+// NewServer creates a server compiling scripts with compile and running
+// at most maxChildren requests at once. configure, if not nil, prepares
+// the VM of each request, for example with the ini settings.
+func NewServer(compile vm.ScriptCompiler, configure func(*vm.VM), maxChildren int) *Server {
+	if maxChildren <= 0 {
+		maxChildren = DefaultMaxChildren
+	}
+	return &Server{
+		compile:   compile,
+		workers:   make(chan struct{}, maxChildren),
+		configure: configure,
+		programs:  make(map[string]*cachedProgram),
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[*conn]bool),
+	}
+}
+
+// SetLogger sets where the server logs script errors and failed
+// connections; the standard logger is used by default
+func (s *Server) SetLogger(logger *log.Logger) {
+	s.logger = logger
+}
+
+// MaxChildren returns the number of requests the server runs at once
+func (s *Server) MaxChildren() int {
+	return cap(s.workers)
+}
+
+// logf logs a message of the server
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.logger != nil {
+		s.logger.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// Reload replaces the configuration of new requests and drops the
+// compiled scripts, which are compiled again on their next request.
+// Requests already running finish with the code and settings they
+// started with.
+func (s *Server) Reload(configure func(*vm.VM)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configure = configure
+	s.programs = make(map[string]*cachedProgram)
+	s.reloads++
+}
+
+// ============================================================================
+// Connections
+// ============================================================================
+
+// Serve accepts connections on a listener until Shutdown, serving each on
+// a goroutine of its own. It returns ErrServerClosed after Shutdown.
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	s.listeners[listener] = struct{}{}
+	s.mu.Unlock()
+
+	for {
+		netConn, err := listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closing := s.closing
+			delete(s.listeners, listener)
+			s.mu.Unlock()
+			if closing {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		c := &conn{server: s, netConn: netConn}
+		s.mu.Lock()
+		if s.closing {
+			s.mu.Unlock()
+			netConn.Close()
+			return ErrServerClosed
+		}
+		s.conns[c] = false
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go c.serve()
+	}
+}
+
+// Shutdown stops the server gracefully: it closes the listeners and the
+// idle connections, then waits for the running requests to finish or the
+// context to end
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	for listener := range s.listeners {
+		listener.Close()
+	}
+	for c, busy := range s.conns {
+		if !busy {
+			c.netConn.Close()
+		}
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// setBusy marks a connection as serving a request or idle. It reports
+// false once the server is shutting down and an idle connection should
+// close.
+func (s *Server) setBusy(c *conn, busy bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conns[c] = busy
+	return busy || !s.closing
+}
+
+// conn is a connection from the web server. Requests on it are served one
+// after another; php-go does not multiplex connections.
+type conn struct {
+	server  *Server
+	netConn net.Conn
+	writer  *bufio.Writer
+}
+
+// request is a request being received on a connection
+type request struct {
+	id       uint16
+	keepConn bool
+	params   map[string]string
+	stdin    []byte
+}
+
+// serve reads the records of a connection and answers its requests
+func (c *conn) serve() {
+	s := c.server
+	defer func() {
+		c.netConn.Close()
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	reader := bufio.NewReader(c.netConn)
+	c.writer = bufio.NewWriter(c.netConn)
+	var req *request
+	for {
+		rec, err := readRecord(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.logf("fastcgi: %v", err)
+			}
+			return
+		}
+
+		switch {
+		case rec.typ == typeGetValues:
+			err = c.answerValues(rec)
+		case rec.id == 0:
+			err = writeRecord(c.writer, typeUnknownType, 0, []byte{byte(rec.typ), 0, 0, 0, 0, 0, 0, 0})
+		case rec.typ == typeBeginRequest:
+			if req != nil {
+				err = writeEndRequest(c.writer, rec.id, 0, statusCantMultiplex)
+				break
+			}
+			if len(rec.content) < 8 {
+				s.logf("fastcgi: malformed BEGIN_REQUEST record")
+				return
+			}
+			role := int(rec.content[0])<<8 | int(rec.content[1])
+			if role != roleResponder {
+				err = writeEndRequest(c.writer, rec.id, 0, statusUnknownRole)
+				break
+			}
+			if !s.setBusy(c, true) {
+				return
+			}
+			req = &request{id: rec.id, keepConn: rec.content[2]&flagKeepConn != 0, params: make(map[string]string)}
+		case req == nil || rec.id != req.id:
+			// Records of a request not in progress are ignored
+			continue
+		case rec.typ == typeParams:
+			// The empty record ending the stream needs no answer
+			if err := readPairs(rec.content, req.params); err != nil {
+				s.logf("%v", err)
+				return
+			}
+		case rec.typ == typeStdin:
+			if len(rec.content) > 0 {
+				req.stdin = append(req.stdin, rec.content...)
+				continue
+			}
+			err = c.respond(req)
+			if err == nil && !req.keepConn {
+				c.writer.Flush()
+				return
+			}
+			req = nil
+			if err == nil && !s.setBusy(c, false) {
+				c.writer.Flush()
+				return
+			}
+		case rec.typ == typeAbortRequest:
+			err = writeEndRequest(c.writer, req.id, 0, statusRequestComplete)
+			keepConn := req.keepConn
+			req = nil
+			if err == nil && (!keepConn || !s.setBusy(c, false)) {
+				c.writer.Flush()
+				return
+			}
+		case rec.typ == typeData:
+			// Only the filter role reads a DATA stream
+		}
+
+		if err == nil {
+			err = c.writer.Flush()
+		}
+		if err != nil {
+			s.logf("fastcgi: %v", err)
+			return
+		}
+	}
+}
+
+// answerValues answers a GET_VALUES record with the values the server
+// knows of those asked
+func (c *conn) answerValues(rec *record) error {
+	asked := make(map[string]string)
+	if err := readPairs(rec.content, asked); err != nil {
+		return err
+	}
+	limit := strconv.Itoa(c.server.MaxChildren())
+	values := map[string]string{
+		"FCGI_MAX_CONNS":  limit,
+		"FCGI_MAX_REQS":   limit,
+		"FCGI_MPXS_CONNS": "0",
+	}
+	var content []byte
+	for name := range asked {
+		if value, ok := values[name]; ok {
+			content = appendPair(content, name, value)
+		}
+	}
+	return writeRecord(c.writer, typeGetValuesResult, 0, content)
+}
+
+// ============================================================================
+// Requests
+// ============================================================================
+
+// respond runs the script of a request on a worker and writes its
+// response: the CGI headers and output on STDOUT, errors on STDERR
+func (c *conn) respond(req *request) error {
+	s := c.server
+	s.workers <- struct{}{}
+	resp, errText := s.run(&sapi.Request{Params: req.params, Body: req.stdin})
+	<-s.workers
+
+	stdout := append(resp.CGIHeader(), resp.Body...)
+	if err := writeStream(c.writer, typeStdout, req.id, stdout); err != nil {
+		return err
+	}
+	if err := writeRecord(c.writer, typeStdout, req.id, nil); err != nil {
+		return err
+	}
+	if errText != "" {
+		if err := writeStream(c.writer, typeStderr, req.id, []byte(errText)); err != nil {
+			return err
+		}
+		if err := writeRecord(c.writer, typeStderr, req.id, nil); err != nil {
+			return err
+		}
+	}
+	return writeEndRequest(c.writer, req.id, 0, statusRequestComplete)
+}
+
+// run runs the script a request names, returning the response and the
+// error to report to the web server, if any
+func (s *Server) run(req *sapi.Request) (*sapi.Response, string) {
+	path := scriptPath(req.Params)
+	info, err := os.Stat(path)
+	if path == "" || err != nil || !info.Mode().IsRegular() {
+		return &sapi.Response{Status: http.StatusNotFound, Body: []byte("File not found.\n")},
+			"Primary script unknown"
+	}
+
+	program, configure, err := s.program(path, info)
+	if err != nil {
+		return &sapi.Response{Status: http.StatusInternalServerError, Body: []byte(err.Error() + "\n")},
+			err.Error()
+	}
+	resp, err := sapi.Run(program, req, configure)
+	if err != nil {
+		message := fmt.Sprintf("PHP Fatal error: %v in %s", err, path)
+		s.logf("%s", message)
+		return resp, message
+	}
+	return resp, ""
+}
+
+// scriptPath returns the script a request names: SCRIPT_FILENAME, or the
+// SCRIPT_NAME under DOCUMENT_ROOT if the web server did not pass it
+func scriptPath(params map[string]string) string {
+	if path := params["SCRIPT_FILENAME"]; path != "" {
+		return path
+	}
+	if params["SCRIPT_NAME"] == "" {
+		return ""
+	}
+	return filepath.Join(params["DOCUMENT_ROOT"], params["SCRIPT_NAME"])
+}
+
+// program returns the compiled program of a script, compiling it if it is
+// not cached or changed on disk, and the configuration of new requests
+func (s *Server) program(path string, info os.FileInfo) (*vm.Program, func(*vm.VM), error) {
+	s.mu.Lock()
+	cached := s.programs[path]
+	configure := s.configure
+	reloads := s.reloads
+	s.mu.Unlock()
+	if cached != nil && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.program, configure, nil
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	script, err := s.compile(path, source)
+	if err != nil {
+		return nil, nil, err
+	}
+	program := vm.NewProgram(script, s.compile)
+
+	s.mu.Lock()
+	if s.reloads == reloads {
+		s.programs[path] = &cachedProgram{program: program, modTime: info.ModTime(), size: info.Size()}
+	}
+	s.mu.Unlock()
+	return program, configure, nil
+}
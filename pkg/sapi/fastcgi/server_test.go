@@ -0,0 +1,243 @@
+package fastcgi
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krizos/php-go/pkg/compiler"
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// testClient is the web server side of a FastCGI connection
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// startServer serves a server on a local port and connects to it
+func startServer(t *testing.T, s *Server) *testClient {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(listener)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	})
+	return dial(t, listener.Addr().String())
+}
+
+func dial(t *testing.T, addr string) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// do sends a request and returns its STDOUT and STDERR streams and the
+// protocol status of its END_REQUEST record
+func (c *testClient) do(id uint16, keepConn bool, params map[string]string, body string) (string, string, uint8) {
+	c.t.Helper()
+	flags := byte(0)
+	if keepConn {
+		flags = flagKeepConn
+	}
+	c.send(typeBeginRequest, id, []byte{0, roleResponder, flags, 0, 0, 0, 0, 0})
+	var pairs []byte
+	for name, value := range params {
+		pairs = appendPair(pairs, name, value)
+	}
+	c.send(typeParams, id, pairs)
+	c.send(typeParams, id, nil)
+	if body != "" {
+		c.send(typeStdin, id, []byte(body))
+	}
+	c.send(typeStdin, id, nil)
+
+	var stdout, stderr strings.Builder
+	for {
+		rec := c.receive()
+		switch rec.typ {
+		case typeStdout:
+			stdout.Write(rec.content)
+		case typeStderr:
+			stderr.Write(rec.content)
+		case typeEndRequest:
+			return stdout.String(), stderr.String(), rec.content[4]
+		default:
+			c.t.Fatalf("Unexpected record type %d", rec.typ)
+		}
+	}
+}
+
+func (c *testClient) send(typ recordType, id uint16, content []byte) {
+	c.t.Helper()
+	if err := writeRecord(c.conn, typ, id, content); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testClient) receive() *record {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	rec, err := readRecord(c.reader)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return rec
+}
+
+func writeScript(t *testing.T, dir, name, source string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPairs(t *testing.T) {
+	long := strings.Repeat("v", 300)
+	data := appendPair(appendPair(nil, "SHORT", "1"), "LONG", long)
+	pairs := make(map[string]string)
+	if err := readPairs(data, pairs); err != nil {
+		t.Fatal(err)
+	}
+	if pairs["SHORT"] != "1" || pairs["LONG"] != long {
+		t.Errorf("Pairs did not round-trip: %v", pairs)
+	}
+	if err := readPairs(data[:len(data)-1], make(map[string]string)); err == nil {
+		t.Error("A truncated pair should fail")
+	}
+}
+
+func TestServer_Request(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, dir, "request.php", `<?php echo $_GET["name"] ?? "", " from ", $_SERVER["REQUEST_METHOD"] ?? "";`)
+	client := startServer(t, NewServer(compiler.ScriptCompiler(compiler.OptimizeNone), nil, 2))
+
+	params := map[string]string{
+		"SCRIPT_FILENAME": script,
+		"REQUEST_METHOD":  "GET",
+		"QUERY_STRING":    "name=World",
+	}
+	stdout, stderr, status := client.do(1, true, params, "")
+	if status != statusRequestComplete || stderr != "" {
+		t.Fatalf("Expected a complete request, got status %d and %q", status, stderr)
+	}
+	if !strings.HasPrefix(stdout, "Status: 200 OK\r\n") || !strings.HasSuffix(stdout, "\r\n\r\nWorld from GET") {
+		t.Errorf("Unexpected response %q", stdout)
+	}
+
+	// The connection is kept for the next request
+	params["QUERY_STRING"] = "name=Again"
+	if stdout, _, _ := client.do(2, true, params, ""); !strings.HasSuffix(stdout, "Again from GET") {
+		t.Errorf("Unexpected response %q", stdout)
+	}
+
+	params["SCRIPT_FILENAME"] = filepath.Join(dir, "missing.php")
+	stdout, stderr, _ = client.do(3, false, params, "")
+	if !strings.HasPrefix(stdout, "Status: 404 Not Found") || stderr != "Primary script unknown" {
+		t.Errorf("A missing script should give a 404, got %q and %q", stdout, stderr)
+	}
+}
+
+func TestServer_GetValues(t *testing.T) {
+	client := startServer(t, NewServer(compiler.ScriptCompiler(compiler.OptimizeNone), nil, 7))
+	client.send(typeGetValues, 0, appendPair(appendPair(nil, "FCGI_MAX_REQS", ""), "FCGI_MPXS_CONNS", ""))
+
+	rec := client.receive()
+	if rec.typ != typeGetValuesResult {
+		t.Fatalf("Expected GET_VALUES_RESULT, got type %d", rec.typ)
+	}
+	values := make(map[string]string)
+	if err := readPairs(rec.content, values); err != nil {
+		t.Fatal(err)
+	}
+	if values["FCGI_MAX_REQS"] != "7" || values["FCGI_MPXS_CONNS"] != "0" {
+		t.Errorf("Unexpected values %v", values)
+	}
+}
+
+func TestServer_UnknownRole(t *testing.T) {
+	client := startServer(t, NewServer(compiler.ScriptCompiler(compiler.OptimizeNone), nil, 1))
+	client.send(typeBeginRequest, 1, []byte{0, roleAuthorizer, 0, 0, 0, 0, 0, 0})
+
+	rec := client.receive()
+	if rec.typ != typeEndRequest || rec.content[4] != statusUnknownRole {
+		t.Errorf("Expected END_REQUEST with FCGI_UNKNOWN_ROLE, got type %d: %v", rec.typ, rec.content)
+	}
+	if appStatus := binary.BigEndian.Uint32(rec.content[:4]); appStatus != 0 {
+		t.Errorf("Expected application status 0, got %d", appStatus)
+	}
+}
+
+func TestServer_Reload(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, dir, "version.php", `<?php echo $version ?? "";`)
+	withVersion := func(version string) func(*vm.VM) {
+		return func(machine *vm.VM) {
+			machine.SetGlobal("version", types.NewString(version))
+		}
+	}
+	s := NewServer(compiler.ScriptCompiler(compiler.OptimizeNone), withVersion("1"), 1)
+	client := startServer(t, s)
+	params := map[string]string{"SCRIPT_FILENAME": script, "REQUEST_METHOD": "GET"}
+
+	if stdout, _, _ := client.do(1, true, params, ""); !strings.HasSuffix(stdout, "\r\n\r\n1") {
+		t.Errorf("Expected version 1, got %q", stdout)
+	}
+	s.mu.Lock()
+	cached := len(s.programs)
+	s.mu.Unlock()
+	if cached != 1 {
+		t.Fatalf("Expected the script to be cached, got %d programs", cached)
+	}
+
+	s.Reload(withVersion("2"))
+	if stdout, _, _ := client.do(2, true, params, ""); !strings.HasSuffix(stdout, "\r\n\r\n2") {
+		t.Errorf("Expected version 2 after the reload, got %q", stdout)
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(compiler.ScriptCompiler(compiler.OptimizeNone), nil, 1)
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(listener) }()
+
+	// An idle connection does not hold the shutdown
+	client := dial(t, listener.Addr().String())
+	client.send(typeGetValues, 0, nil)
+	client.receive()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error: %v", err)
+	}
+	if err := <-served; err != ErrServerClosed {
+		t.Errorf("Serve() should return ErrServerClosed, got %v", err)
+	}
+	if _, err := net.Dial("tcp", listener.Addr().String()); err == nil {
+		t.Error("The listener should be closed")
+	}
+}
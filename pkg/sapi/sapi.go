@@ -0,0 +1,278 @@
+// Package sapi runs PHP programs for web servers. It fills the
+// superglobals of a request from its CGI variables and body, runs the
+// program in a VM of its own and builds the response from the headers and
+// output of the script.
+package sapi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/krizos/php-go/pkg/stdlib/filter"
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// Request is a web request in CGI terms
+type Request struct {
+	Params map[string]string // CGI variables: REQUEST_METHOD, QUERY_STRING, HTTP_*...
+	Body   []byte            // Request body (FastCGI stdin)
+}
+
+// Response is the answer of a program to a request
+type Response struct {
+	Status  int
+	Headers []string // Header lines ("Name: value")
+	Body    []byte
+}
+
+// maxMultipartMemory bounds the memory used to parse a multipart body
+const maxMultipartMemory = 8 << 20
+
+// Run executes a program for a request in a new VM, configured by
+// configure if it is not nil. The response has the status code and
+// headers the script set with header(), http_response_code() and
+// setcookie(). A script failing with a fatal error gets a 500 response
+// with the output it produced, and the error is returned for the server
+// log.
+func Run(program *vm.Program, req *Request, configure func(*vm.VM)) (*Response, error) {
+	machine := program.NewRequest()
+	machine.SetResponseCode(http.StatusOK)
+	if configure != nil {
+		configure(machine)
+	}
+	Populate(machine, req)
+
+	err := machine.ExecuteProgram()
+	resp := &Response{
+		Status:  machine.ResponseCode(),
+		Headers: machine.ResponseHeaders(),
+		Body:    []byte(machine.GetOutput()),
+	}
+	if err != nil {
+		resp.Status = http.StatusInternalServerError
+	}
+	return resp, err
+}
+
+// ============================================================================
+// Superglobals
+// ============================================================================
+
+// Populate fills the superglobals of a request from its CGI variables and
+// body, in the sources variables_order enables: $_SERVER, $_GET, $_POST,
+// $_COOKIE and $_REQUEST, which merges the input in GPC order
+func Populate(machine *vm.VM, req *Request) {
+	order, _ := machine.Config().Get("variables_order")
+	order = strings.ToUpper(order)
+
+	sources := map[byte]*types.Array{
+		'G': parseQuery(req.Params["QUERY_STRING"]),
+		'P': parseBody(req.Params["REQUEST_METHOD"], req.Params["CONTENT_TYPE"], req.Body),
+		'C': parseCookies(req.Params["HTTP_COOKIE"]),
+		'S': serverVariables(req.Params),
+	}
+	inputs := map[byte]int64{
+		'G': filter.INPUT_GET,
+		'P': filter.INPUT_POST,
+		'C': filter.INPUT_COOKIE,
+		'S': filter.INPUT_SERVER,
+	}
+
+	request := types.NewEmptyArray()
+	for _, source := range []byte("GPCS") {
+		data := sources[source]
+		if !strings.ContainsRune(order, rune(source)) {
+			data = types.NewEmptyArray()
+		}
+		machine.SetRequestInput(inputs[source], data)
+		if source != 'S' {
+			data.Each(func(key, value *types.Value) bool {
+				request.Set(key, value.Copy())
+				return true
+			})
+		}
+	}
+	machine.SetGlobal("_REQUEST", types.NewArray(request))
+}
+
+// serverVariables builds $_SERVER from the CGI variables, as PHP-FPM passes
+// them all, with the request time and PHP_SELF added
+func serverVariables(params map[string]string) *types.Array {
+	server := types.NewEmptyArray()
+	for name, value := range params {
+		server.Set(types.NewString(name), types.NewString(value))
+	}
+	if _, ok := params["PHP_SELF"]; !ok {
+		server.Set(types.NewString("PHP_SELF"), types.NewString(params["SCRIPT_NAME"]+params["PATH_INFO"]))
+	}
+	now := time.Now()
+	server.Set(types.NewString("REQUEST_TIME"), types.NewInt(now.Unix()))
+	server.Set(types.NewString("REQUEST_TIME_FLOAT"), types.NewFloat(float64(now.UnixMicro())/1e6))
+	return server
+}
+
+// parseQuery parses a query string into the variables of $_GET
+func parseQuery(query string) *types.Array {
+	vars := types.NewEmptyArray()
+	for _, pair := range strings.FieldsFunc(query, func(r rune) bool { return r == '&' }) {
+		name, value, _ := strings.Cut(pair, "=")
+		name, err := url.QueryUnescape(name)
+		if err != nil {
+			continue
+		}
+		value, err = url.QueryUnescape(value)
+		if err != nil {
+			continue
+		}
+		registerVariable(vars, name, types.NewString(value))
+	}
+	return vars
+}
+
+// parseBody parses the form data of a POST body into the variables of
+// $_POST: URL-encoded or multipart, whose file parts are left out
+func parseBody(method, contentType string, body []byte) *types.Array {
+	if method != "POST" || contentType == "" {
+		return types.NewEmptyArray()
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return types.NewEmptyArray()
+	}
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		return parseQuery(string(body))
+	case "multipart/form-data":
+		vars := types.NewEmptyArray()
+		reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			if part.FileName() != "" || part.FormName() == "" {
+				continue
+			}
+			value, err := io.ReadAll(io.LimitReader(part, maxMultipartMemory))
+			if err != nil {
+				break
+			}
+			registerVariable(vars, part.FormName(), types.NewString(string(value)))
+		}
+		return vars
+	}
+	return types.NewEmptyArray()
+}
+
+// parseCookies parses a Cookie header into the variables of $_COOKIE
+func parseCookies(header string) *types.Array {
+	vars := types.NewEmptyArray()
+	for _, cookie := range strings.Split(header, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(cookie), "=")
+		if !ok || name == "" {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(value); err == nil {
+			value = unescaped
+		}
+		// The first cookie of a name wins, as the most specific one
+		if _, exists := vars.Get(types.NewString(name)); !exists {
+			registerVariable(vars, name, types.NewString(value))
+		}
+	}
+	return vars
+}
+
+// registerVariable stores a request variable the way PHP names it: dots
+// and spaces in the name become underscores, and brackets nest arrays, as
+// in a[]=1 or a[x][y]=2
+func registerVariable(vars *types.Array, name string, value *types.Value) {
+	name = strings.TrimLeft(name, " ")
+	base, keys := name, []string(nil)
+	if open := strings.IndexByte(name, '['); open >= 0 {
+		base = name[:open]
+		rest := name[open:]
+		for len(rest) > 0 && rest[0] == '[' {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				// An unmatched first bracket is part of the name
+				if keys == nil {
+					base = name[:open] + "_" + name[open+1:]
+				}
+				break
+			}
+			keys = append(keys, rest[1:end])
+			rest = rest[end+1:]
+		}
+	}
+	base = strings.Map(func(r rune) rune {
+		if r == '.' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, base)
+	if base == "" {
+		return
+	}
+
+	container, key := vars, base
+	for _, next := range keys {
+		var inner *types.Array
+		if key != "" {
+			if existing, ok := container.Get(types.NewString(key)); ok && existing.IsArray() {
+				inner = existing.ToArray()
+			}
+		}
+		if inner == nil {
+			inner = types.NewEmptyArray()
+			setElement(container, key, types.NewArray(inner))
+		}
+		container, key = inner, next
+	}
+	setElement(container, key, value)
+}
+
+// setElement sets an array element, appending for an empty key that is
+// not the top-level name
+func setElement(container *types.Array, key string, value *types.Value) {
+	if key == "" {
+		container.Append(value)
+		return
+	}
+	container.Set(types.NewString(key), value)
+}
+
+// ============================================================================
+// Responses
+// ============================================================================
+
+// CGIHeader returns the header block of a response as a CGI program
+// writes it: a Status line, the headers and a Content-Type unless the
+// script sent one, ending with a blank line
+func (r *Response) CGIHeader() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Status: %d %s\r\n", r.Status, http.StatusText(r.Status))
+	hasContentType := false
+	for _, header := range r.Headers {
+		name, _, _ := strings.Cut(header, ":")
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Type") {
+			hasContentType = true
+		}
+		buf.WriteString(header)
+		buf.WriteString("\r\n")
+	}
+	if !hasContentType {
+		buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,142 @@
+package sapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/types"
+	"github.com/krizos/php-go/pkg/vm"
+)
+
+// element returns the element of an array value at a path of string keys
+func element(t *testing.T, value *types.Value, keys ...string) *types.Value {
+	t.Helper()
+	for _, key := range keys {
+		if value == nil || !value.IsArray() {
+			t.Fatalf("Expected an array holding %q, got %v", key, value)
+		}
+		var ok bool
+		value, ok = value.ToArray().Get(types.NewString(key))
+		if !ok {
+			t.Fatalf("Missing key %q", key)
+		}
+	}
+	return value
+}
+
+func global(t *testing.T, machine *vm.VM, name string) *types.Value {
+	t.Helper()
+	value, ok := machine.GetGlobal(name)
+	if !ok {
+		t.Fatalf("$%s is not set", name)
+	}
+	return value
+}
+
+func TestPopulate(t *testing.T) {
+	machine := vm.New()
+	Populate(machine, &Request{
+		Params: map[string]string{
+			"REQUEST_METHOD":  "POST",
+			"QUERY_STRING":    "name=World&a.b=1&list[]=x&list[]=y&m[k][j]=deep",
+			"CONTENT_TYPE":    "application/x-www-form-urlencoded",
+			"HTTP_COOKIE":     "sid=abc%20def; name=cookie",
+			"SCRIPT_NAME":     "/index.php",
+			"SCRIPT_FILENAME": "/var/www/index.php",
+		},
+		Body: []byte("name=Posted&count=3"),
+	})
+
+	get := global(t, machine, "_GET")
+	if got := element(t, get, "name").ToString(); got != "World" {
+		t.Errorf("$_GET['name']: expected World, got %q", got)
+	}
+	if got := element(t, get, "a_b").ToString(); got != "1" {
+		t.Errorf("$_GET['a_b']: expected 1, got %q", got)
+	}
+	if got := element(t, get, "list").ToArray().Len(); got != 2 {
+		t.Errorf("$_GET['list']: expected 2 elements, got %d", got)
+	}
+	if got := element(t, get, "m", "k", "j").ToString(); got != "deep" {
+		t.Errorf("$_GET['m']['k']['j']: expected deep, got %q", got)
+	}
+
+	if got := element(t, global(t, machine, "_POST"), "count").ToString(); got != "3" {
+		t.Errorf("$_POST['count']: expected 3, got %q", got)
+	}
+	if got := element(t, global(t, machine, "_COOKIE"), "sid").ToString(); got != "abc def" {
+		t.Errorf("$_COOKIE['sid']: expected \"abc def\", got %q", got)
+	}
+
+	server := global(t, machine, "_SERVER")
+	if got := element(t, server, "SCRIPT_FILENAME").ToString(); got != "/var/www/index.php" {
+		t.Errorf("$_SERVER['SCRIPT_FILENAME']: got %q", got)
+	}
+	if got := element(t, server, "PHP_SELF").ToString(); got != "/index.php" {
+		t.Errorf("$_SERVER['PHP_SELF']: got %q", got)
+	}
+	if element(t, server, "REQUEST_TIME").ToInt() <= 0 {
+		t.Error("$_SERVER['REQUEST_TIME'] should be set")
+	}
+
+	// $_REQUEST merges GET, POST and COOKIE in that order
+	if got := element(t, global(t, machine, "_REQUEST"), "name").ToString(); got != "cookie" {
+		t.Errorf("$_REQUEST['name']: expected cookie, got %q", got)
+	}
+}
+
+func TestPopulate_VariablesOrder(t *testing.T) {
+	machine := vm.New()
+	if _, err := machine.Config().Set("variables_order", "GS", runtime.INI_SYSTEM); err != nil {
+		t.Fatal(err)
+	}
+	Populate(machine, &Request{Params: map[string]string{
+		"REQUEST_METHOD": "GET",
+		"QUERY_STRING":   "q=1",
+		"HTTP_COOKIE":    "c=2",
+	}})
+
+	if global(t, machine, "_COOKIE").ToArray().Len() != 0 {
+		t.Error("$_COOKIE should be empty without C in variables_order")
+	}
+	if got := element(t, global(t, machine, "_REQUEST"), "q").ToString(); got != "1" {
+		t.Errorf("$_REQUEST['q']: expected 1, got %q", got)
+	}
+}
+
+func TestParseBody_Multipart(t *testing.T) {
+	body := strings.Join([]string{
+		"--XYZ",
+		`Content-Disposition: form-data; name="title"`,
+		"",
+		"Hello",
+		"--XYZ",
+		`Content-Disposition: form-data; name="upload"; filename="a.txt"`,
+		"",
+		"file data",
+		"--XYZ--",
+		"",
+	}, "\r\n")
+
+	vars := parseBody("POST", "multipart/form-data; boundary=XYZ", []byte(body))
+	if vars.Len() != 1 {
+		t.Fatalf("Expected only the form field, got %d variables", vars.Len())
+	}
+	if got := element(t, types.NewArray(vars), "title").ToString(); got != "Hello" {
+		t.Errorf("Expected Hello, got %q", got)
+	}
+}
+
+func TestCGIHeader(t *testing.T) {
+	resp := &Response{Status: 404, Headers: []string{"X-Test: 1"}}
+	want := "Status: 404 Not Found\r\nX-Test: 1\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n"
+	if got := string(resp.CGIHeader()); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	resp.Headers = []string{"content-type: application/json"}
+	if got := string(resp.CGIHeader()); strings.Contains(got, "text/html") {
+		t.Errorf("A Content-Type of the script should replace the default, got %q", got)
+	}
+}
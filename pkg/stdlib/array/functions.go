@@ -1,6 +1,9 @@
 package array
 
 import (
+	"sort"
+
+	"github.com/krizos/php-go/pkg/stdlib"
 	"github.com/krizos/php-go/pkg/types"
 )
 
@@ -124,7 +127,7 @@ func ArrayMerge(arrays ...*types.Value) *types.Value {
 	// Start with the first array
 	var result *types.Array
 	if arrays[0] != nil && arrays[0].Type() == types.TypeArray {
-		result = arrays[0].ToArray().DeepCopy()
+		result = arrays[0].ToArray().Copy()
 	} else {
 		result = types.NewEmptyArray()
 	}
@@ -151,11 +154,7 @@ func InArray(needle *types.Value, haystack *types.Value, strict ...*types.Value)
 		return types.NewBool(false)
 	}
 
-	arrayData := haystack.ToArray()
-
-	// TODO: strict comparison mode when strict is true
-	// For now, we use loose comparison (Equals)
-	found := arrayData.Contains(needle)
+	_, found := search(needle, haystack.ToArray(), strict)
 	return types.NewBool(found)
 }
 
@@ -166,16 +165,22 @@ func ArraySearch(needle *types.Value, haystack *types.Value, strict ...*types.Va
 		return types.NewBool(false)
 	}
 
-	arrayData := haystack.ToArray()
-
-	// TODO: strict comparison mode when strict is true
-	key, found := arrayData.Search(needle)
+	key, found := search(needle, haystack.ToArray(), strict)
 	if !found {
 		return types.NewBool(false)
 	}
 	return key
 }
 
+// search finds the first key for a value equal to needle, or identical to
+// it if the strict argument is true
+func search(needle *types.Value, haystack *types.Array, strict []*types.Value) (*types.Value, bool) {
+	if len(strict) > 0 && strict[0].ToBool() {
+		return haystack.SearchStrict(needle)
+	}
+	return haystack.Search(needle)
+}
+
 // ============================================================================
 // Array Slicing
 // ============================================================================
@@ -447,161 +452,103 @@ func ArrayChunk(arr *types.Value, length *types.Value, preserveKeys ...*types.Va
 // Sort sorts an array by values in ascending order
 // sort(array &$array, int $flags = SORT_REGULAR): true
 func Sort(arr *types.Value, flags ...*types.Value) *types.Value {
-	if arr == nil || arr.Type() != types.TypeArray {
-		return types.NewBool(false)
-	}
-
-	arrayData := arr.ToArray()
-
-	// Collect values
-	var values []*types.Value
-	arrayData.Each(func(_, value *types.Value) bool {
-		values = append(values, value)
-		return true
-	})
-
-	// Sort values
-	sortValues(values, false)
-
-	// Reset array and add sorted values with numeric keys
-	arrayData.Reset()
-	for i, val := range values {
-		arrayData.Set(types.NewInt(int64(i)), val)
-	}
-
-	return types.NewBool(true)
+	return sortList(arr, sortComparator(flags), false)
 }
 
 // Rsort sorts an array by values in descending order
 // rsort(array &$array, int $flags = SORT_REGULAR): true
 func Rsort(arr *types.Value, flags ...*types.Value) *types.Value {
-	if arr == nil || arr.Type() != types.TypeArray {
-		return types.NewBool(false)
-	}
-
-	arrayData := arr.ToArray()
-
-	// Collect values
-	var values []*types.Value
-	arrayData.Each(func(_, value *types.Value) bool {
-		values = append(values, value)
-		return true
-	})
-
-	// Sort values in reverse
-	sortValues(values, true)
-
-	// Reset array and add sorted values with numeric keys
-	arrayData.Reset()
-	for i, val := range values {
-		arrayData.Set(types.NewInt(int64(i)), val)
-	}
-
-	return types.NewBool(true)
+	return sortList(arr, sortComparator(flags), true)
 }
 
 // Asort sorts an array by values in ascending order, preserving keys
 // asort(array &$array, int $flags = SORT_REGULAR): true
 func Asort(arr *types.Value, flags ...*types.Value) *types.Value {
-	if arr == nil || arr.Type() != types.TypeArray {
-		return types.NewBool(false)
-	}
-
-	arrayData := arr.ToArray()
-
-	// Collect key-value pairs
-	var pairs []struct{ key, value *types.Value }
-	arrayData.Each(func(key, value *types.Value) bool {
-		pairs = append(pairs, struct{ key, value *types.Value }{key, value})
-		return true
-	})
-
-	// Sort by values
-	sortPairsByValue(pairs, false)
-
-	// Reset array and add sorted pairs
-	arrayData.Reset()
-	for _, pair := range pairs {
-		arrayData.Set(pair.key, pair.value)
-	}
-
-	return types.NewBool(true)
+	return sortByValue(arr, sortComparator(flags), false)
 }
 
 // Arsort sorts an array by values in descending order, preserving keys
 // arsort(array &$array, int $flags = SORT_REGULAR): true
 func Arsort(arr *types.Value, flags ...*types.Value) *types.Value {
-	if arr == nil || arr.Type() != types.TypeArray {
-		return types.NewBool(false)
-	}
-
-	arrayData := arr.ToArray()
-
-	// Collect key-value pairs
-	var pairs []struct{ key, value *types.Value }
-	arrayData.Each(func(key, value *types.Value) bool {
-		pairs = append(pairs, struct{ key, value *types.Value }{key, value})
-		return true
-	})
-
-	// Sort by values in reverse
-	sortPairsByValue(pairs, true)
-
-	// Reset array and add sorted pairs
-	arrayData.Reset()
-	for _, pair := range pairs {
-		arrayData.Set(pair.key, pair.value)
-	}
-
-	return types.NewBool(true)
+	return sortByValue(arr, sortComparator(flags), true)
 }
 
 // Ksort sorts an array by keys in ascending order
 // ksort(array &$array, int $flags = SORT_REGULAR): true
 func Ksort(arr *types.Value, flags ...*types.Value) *types.Value {
+	return sortByKey(arr, sortComparator(flags), false)
+}
+
+// Krsort sorts an array by keys in descending order
+// krsort(array &$array, int $flags = SORT_REGULAR): true
+func Krsort(arr *types.Value, flags ...*types.Value) *types.Value {
+	return sortByKey(arr, sortComparator(flags), true)
+}
+
+// Usort sorts an array by values using a user-defined comparison function
+// usort(array &$array, callable $callback): true
+func Usort(caller stdlib.Caller, arr *types.Value, callback *types.Value) (*types.Value, error) {
 	if arr == nil || arr.Type() != types.TypeArray {
-		return types.NewBool(false)
+		return types.NewBool(false), nil
 	}
 
 	arrayData := arr.ToArray()
+	values := arrayValues(arrayData)
 
-	// Collect key-value pairs
-	var pairs []struct{ key, value *types.Value }
-	arrayData.Each(func(key, value *types.Value) bool {
-		pairs = append(pairs, struct{ key, value *types.Value }{key, value})
-		return true
+	var err error
+	sort.SliceStable(values, func(i, j int) bool {
+		return userLess(caller, callback, values[i], values[j], &err)
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	// Sort by keys
-	sortPairsByKey(pairs, false)
-
-	// Reset array and add sorted pairs
+	// Reset array and re-index
 	arrayData.Reset()
-	for _, pair := range pairs {
-		arrayData.Set(pair.key, pair.value)
+	for _, value := range values {
+		arrayData.Append(value)
 	}
 
-	return types.NewBool(true)
+	return types.NewBool(true), nil
 }
 
-// Krsort sorts an array by keys in descending order
-// krsort(array &$array, int $flags = SORT_REGULAR): true
-func Krsort(arr *types.Value, flags ...*types.Value) *types.Value {
+// Uasort sorts an array by values using a user-defined comparison function,
+// maintaining index association
+// uasort(array &$array, callable $callback): true
+func Uasort(caller stdlib.Caller, arr *types.Value, callback *types.Value) (*types.Value, error) {
+	return userSortPairs(caller, arr, callback, false)
+}
+
+// Uksort sorts an array by keys using a user-defined comparison function
+// uksort(array &$array, callable $callback): true
+func Uksort(caller stdlib.Caller, arr *types.Value, callback *types.Value) (*types.Value, error) {
+	return userSortPairs(caller, arr, callback, true)
+}
+
+// userSortPairs sorts key/value pairs with a user comparator, keeping keys
+func userSortPairs(caller stdlib.Caller, arr *types.Value, callback *types.Value, byKey bool) (*types.Value, error) {
 	if arr == nil || arr.Type() != types.TypeArray {
-		return types.NewBool(false)
+		return types.NewBool(false), nil
 	}
 
 	arrayData := arr.ToArray()
 
-	// Collect key-value pairs
 	var pairs []struct{ key, value *types.Value }
 	arrayData.Each(func(key, value *types.Value) bool {
 		pairs = append(pairs, struct{ key, value *types.Value }{key, value})
 		return true
 	})
 
-	// Sort by keys in reverse
-	sortPairsByKey(pairs, true)
+	var err error
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if byKey {
+			return userLess(caller, callback, pairs[i].key, pairs[j].key, &err)
+		}
+		return userLess(caller, callback, pairs[i].value, pairs[j].value, &err)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	// Reset array and add sorted pairs
 	arrayData.Reset()
@@ -609,7 +556,21 @@ func Krsort(arr *types.Value, flags ...*types.Value) *types.Value {
 		arrayData.Set(pair.key, pair.value)
 	}
 
-	return types.NewBool(true)
+	return types.NewBool(true), nil
+}
+
+// userLess calls a user comparator and reports whether a sorts before b.
+// The first callback error is stored in errp; later comparisons are skipped.
+func userLess(caller stdlib.Caller, callback, a, b *types.Value, errp *error) bool {
+	if *errp != nil {
+		return false
+	}
+	result, err := caller.CallCallable(callback, []*types.Value{a, b})
+	if err != nil {
+		*errp = err
+		return false
+	}
+	return result.ToInt() < 0
 }
 
 // ============================================================================
@@ -617,79 +578,208 @@ func Krsort(arr *types.Value, flags ...*types.Value) *types.Value {
 // ============================================================================
 
 // ArrayMap applies a callback to the elements of an array
-// array_map(callable $callback, array ...$arrays): array
-func ArrayMap(callback *types.Value, arrays ...*types.Value) *types.Value {
+// array_map(?callable $callback, array $array, array ...$arrays): array
+func ArrayMap(caller stdlib.Caller, callback *types.Value, arrays ...*types.Value) (*types.Value, error) {
 	if len(arrays) == 0 {
-		return types.NewArray(types.NewEmptyArray())
+		return types.NewArray(types.NewEmptyArray()), nil
 	}
 
-	// For now, support single array
-	if arrays[0] == nil || arrays[0].Type() != types.TypeArray {
-		return types.NewArray(types.NewEmptyArray())
+	for _, arr := range arrays {
+		if arr == nil || arr.Type() != types.TypeArray {
+			return types.NewArray(types.NewEmptyArray()), nil
+		}
 	}
 
-	arrayData := arrays[0].ToArray()
 	result := types.NewEmptyArray()
 
-	// TODO: Implement callback invocation when we have callable support
-	// For now, just copy the array
-	arrayData.Each(func(key, value *types.Value) bool {
-		result.Append(value)
-		return true
-	})
+	// A single array keeps its keys
+	if len(arrays) == 1 {
+		var err error
+		arrays[0].ToArray().Each(func(key, value *types.Value) bool {
+			if callback == nil || callback.IsNull() {
+				result.Set(key, value)
+				return true
+			}
+			var mapped *types.Value
+			mapped, err = caller.CallCallable(callback, []*types.Value{value})
+			if err != nil {
+				return false
+			}
+			result.Set(key, mapped)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		return types.NewArray(result), nil
+	}
 
-	return types.NewArray(result)
+	// Multiple arrays are walked in parallel, padding shorter ones with null
+	columns := make([][]*types.Value, len(arrays))
+	longest := 0
+	for i, arr := range arrays {
+		columns[i] = arrayValues(arr.ToArray())
+		if len(columns[i]) > longest {
+			longest = len(columns[i])
+		}
+	}
+
+	for row := 0; row < longest; row++ {
+		args := make([]*types.Value, len(columns))
+		for i, column := range columns {
+			if row < len(column) {
+				args[i] = column[row]
+			} else {
+				args[i] = types.NewNull()
+			}
+		}
+
+		// A null callback zips the arrays together
+		if callback == nil || callback.IsNull() {
+			result.Append(types.NewArray(types.NewArrayFromSlice(args)))
+			continue
+		}
+
+		mapped, err := caller.CallCallable(callback, args)
+		if err != nil {
+			return nil, err
+		}
+		result.Append(mapped)
+	}
+
+	return types.NewArray(result), nil
 }
 
+// array_filter() modes
+const (
+	ArrayFilterUseBoth = 1 // ARRAY_FILTER_USE_BOTH: pass value and key
+	ArrayFilterUseKey  = 2 // ARRAY_FILTER_USE_KEY: pass key only
+)
+
 // ArrayFilter filters elements of an array using a callback function
 // array_filter(array $array, ?callable $callback = null, int $mode = 0): array
-func ArrayFilter(arr *types.Value, callback ...*types.Value) *types.Value {
+func ArrayFilter(caller stdlib.Caller, arr *types.Value, args ...*types.Value) (*types.Value, error) {
 	if arr == nil || arr.Type() != types.TypeArray {
-		return types.NewArray(types.NewEmptyArray())
+		return types.NewArray(types.NewEmptyArray()), nil
+	}
+
+	var callback *types.Value
+	if len(args) > 0 && args[0] != nil && !args[0].IsNull() {
+		callback = args[0]
+	}
+	mode := int64(0)
+	if len(args) > 1 && args[1] != nil {
+		mode = args[1].ToInt()
 	}
 
 	arrayData := arr.ToArray()
 	result := types.NewEmptyArray()
 
-	// If no callback, filter out false-y values
+	var err error
 	arrayData.Each(func(key, value *types.Value) bool {
-		if value.ToBool() {
+		// If no callback, filter out false-y values
+		if callback == nil {
+			if value.ToBool() {
+				result.Set(key, value)
+			}
+			return true
+		}
+
+		var callArgs []*types.Value
+		switch mode {
+		case ArrayFilterUseKey:
+			callArgs = []*types.Value{key}
+		case ArrayFilterUseBoth:
+			callArgs = []*types.Value{value, key}
+		default:
+			callArgs = []*types.Value{value}
+		}
+
+		var keep *types.Value
+		keep, err = caller.CallCallable(callback, callArgs)
+		if err != nil {
+			return false
+		}
+		if keep.ToBool() {
 			result.Set(key, value)
 		}
 		return true
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return types.NewArray(result)
+	return types.NewArray(result), nil
 }
 
 // ArrayReduce reduces an array to a single value using a callback
 // array_reduce(array $array, callable $callback, mixed $initial = null): mixed
-func ArrayReduce(arr *types.Value, callback *types.Value, initial ...*types.Value) *types.Value {
+func ArrayReduce(caller stdlib.Caller, arr *types.Value, callback *types.Value, initial ...*types.Value) (*types.Value, error) {
+	carry := types.NewNull()
+	if len(initial) > 0 && initial[0] != nil {
+		carry = initial[0]
+	}
+
 	if arr == nil || arr.Type() != types.TypeArray {
-		if len(initial) > 0 {
-			return initial[0]
-		}
-		return types.NewNull()
+		return carry, nil
 	}
 
-	// TODO: Implement callback invocation when we have callable support
-	// For now, return the initial value or null
-	if len(initial) > 0 {
-		return initial[0]
+	var err error
+	arr.ToArray().Each(func(_, value *types.Value) bool {
+		carry, err = caller.CallCallable(callback, []*types.Value{carry, value})
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return types.NewNull()
+
+	return carry, nil
 }
 
 // ArrayWalk applies a user function to every member of an array
 // array_walk(array &$array, callable $callback, mixed $arg = null): true
-func ArrayWalk(arr *types.Value, callback *types.Value, arg ...*types.Value) *types.Value {
+func ArrayWalk(caller stdlib.Caller, arr *types.Value, callback *types.Value, arg ...*types.Value) (*types.Value, error) {
 	if arr == nil || arr.Type() != types.TypeArray {
-		return types.NewBool(false)
+		return types.NewBool(false), nil
 	}
 
-	// TODO: Implement callback invocation when we have callable support
-	// For now, just return true
-	return types.NewBool(true)
+	arrayData := arr.ToArray()
+
+	// Collect pairs first so the callback may modify the array
+	var pairs []struct{ key, value *types.Value }
+	arrayData.Each(func(key, value *types.Value) bool {
+		pairs = append(pairs, struct{ key, value *types.Value }{key, value})
+		return true
+	})
+
+	for _, pair := range pairs {
+		// The value is passed by reference (function(&$value, $key))
+		ref := types.NewReference(pair.value)
+		args := []*types.Value{ref, pair.key}
+		if len(arg) > 0 && arg[0] != nil {
+			args = append(args, arg[0])
+		}
+
+		if _, err := caller.CallCallable(callback, args); err != nil {
+			return nil, err
+		}
+
+		if updated := ref.Deref(); updated != pair.value {
+			arrayData.Set(pair.key, updated)
+		}
+	}
+
+	return types.NewBool(true), nil
+}
+
+// arrayValues returns the values of an array in order
+func arrayValues(arr *types.Array) []*types.Value {
+	values := make([]*types.Value, 0, arr.Len())
+	arr.Each(func(_, value *types.Value) bool {
+		values = append(values, value)
+		return true
+	})
+	return values
 }
 
 // ============================================================================
@@ -775,6 +865,58 @@ func ArrayIntersect(arrays ...*types.Value) *types.Value {
 	return types.NewArray(result)
 }
 
+// ArrayUdiff computes the difference of arrays using a callback for value comparison
+// array_udiff(array $array, array ...$arrays, callable $value_compare_func): array
+func ArrayUdiff(caller stdlib.Caller, args ...*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return types.NewArray(types.NewEmptyArray()), nil
+	}
+
+	callback := args[len(args)-1]
+	arrays := args[:len(args)-1]
+
+	if arrays[0] == nil || arrays[0].Type() != types.TypeArray {
+		return types.NewArray(types.NewEmptyArray()), nil
+	}
+
+	base := arrays[0].ToArray()
+	result := types.NewEmptyArray()
+
+	var err error
+	base.Each(func(key, value *types.Value) bool {
+		found := false
+
+		// Check if any value of the other arrays compares equal
+		for i := 1; i < len(arrays) && !found; i++ {
+			if arrays[i] == nil || arrays[i].Type() != types.TypeArray {
+				continue
+			}
+			arrays[i].ToArray().Each(func(_, other *types.Value) bool {
+				var cmp *types.Value
+				cmp, err = caller.CallCallable(callback, []*types.Value{value, other})
+				if err != nil {
+					return false
+				}
+				found = cmp.ToInt() == 0
+				return !found
+			})
+			if err != nil {
+				return false
+			}
+		}
+
+		if !found {
+			result.Set(key, value)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return types.NewArray(result), nil
+}
+
 // ============================================================================
 // Array Pointer Functions
 // ============================================================================
@@ -911,79 +1053,74 @@ func Prev(arr *types.Value) *types.Value {
 // Helper Functions
 // ============================================================================
 
-// sortValues sorts a slice of values in place
-func sortValues(values []*types.Value, reverse bool) {
-	for i := 0; i < len(values)-1; i++ {
-		for j := i + 1; j < len(values); j++ {
-			if compareValues(values[i], values[j], reverse) {
-				values[i], values[j] = values[j], values[i]
-			}
-		}
+// sortList sorts the values of an array and renumbers them from 0. As
+// of PHP 8.0 sorting is stable: equal values keep their order.
+func sortList(arr *types.Value, compare comparator, reverse bool) *types.Value {
+	if arr == nil || arr.Type() != types.TypeArray {
+		return types.NewBool(false)
 	}
-}
 
-// sortPairsByValue sorts pairs by their value
-func sortPairsByValue(pairs []struct{ key, value *types.Value }, reverse bool) {
-	for i := 0; i < len(pairs)-1; i++ {
-		for j := i + 1; j < len(pairs); j++ {
-			if compareValues(pairs[i].value, pairs[j].value, reverse) {
-				pairs[i], pairs[j] = pairs[j], pairs[i]
-			}
-		}
+	arrayData := arr.ToArray()
+	values := arrayValues(arrayData)
+	sort.SliceStable(values, func(i, j int) bool {
+		return sortsBefore(compare, values[i], values[j], reverse)
+	})
+
+	// Reset array and add sorted values with numeric keys
+	arrayData.Reset()
+	for _, value := range values {
+		arrayData.Append(value)
 	}
+
+	return types.NewBool(true)
 }
 
-// sortPairsByKey sorts pairs by their key
-func sortPairsByKey(pairs []struct{ key, value *types.Value }, reverse bool) {
-	for i := 0; i < len(pairs)-1; i++ {
-		for j := i + 1; j < len(pairs); j++ {
-			if compareValues(pairs[i].key, pairs[j].key, reverse) {
-				pairs[i], pairs[j] = pairs[j], pairs[i]
-			}
-		}
-	}
+// sortByValue stably sorts an array by its values, preserving keys
+func sortByValue(arr *types.Value, compare comparator, reverse bool) *types.Value {
+	return sortPairs(arr, compare, reverse, false)
 }
 
-// compareValues compares two values for sorting
-// Returns true if a should come after b
-func compareValues(a, b *types.Value, reverse bool) bool {
-	if a == nil || b == nil {
-		return false
+// sortByKey stably sorts an array by its keys
+func sortByKey(arr *types.Value, compare comparator, reverse bool) *types.Value {
+	return sortPairs(arr, compare, reverse, true)
+}
+
+// sortPairs stably sorts the key/value pairs of an array by key or by
+// value
+func sortPairs(arr *types.Value, compare comparator, reverse, byKey bool) *types.Value {
+	if arr == nil || arr.Type() != types.TypeArray {
+		return types.NewBool(false)
 	}
 
-	// Compare based on type
-	aType := a.Type()
-	bType := b.Type()
+	arrayData := arr.ToArray()
 
-	// Numbers
-	if aType == types.TypeInt || aType == types.TypeFloat {
-		if bType == types.TypeInt || bType == types.TypeFloat {
-			aNum := a.ToFloat()
-			bNum := b.ToFloat()
-			if reverse {
-				return aNum < bNum
-			}
-			return aNum > bNum
-		}
-	}
+	var pairs []struct{ key, value *types.Value }
+	arrayData.Each(func(key, value *types.Value) bool {
+		pairs = append(pairs, struct{ key, value *types.Value }{key, value})
+		return true
+	})
 
-	// Strings
-	if aType == types.TypeString {
-		if bType == types.TypeString {
-			aStr := a.ToString()
-			bStr := b.ToString()
-			if reverse {
-				return aStr < bStr
-			}
-			return aStr > bStr
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if byKey {
+			return sortsBefore(compare, pairs[i].key, pairs[j].key, reverse)
 		}
+		return sortsBefore(compare, pairs[i].value, pairs[j].value, reverse)
+	})
+
+	// Reset array and add sorted pairs
+	arrayData.Reset()
+	for _, pair := range pairs {
+		arrayData.Set(pair.key, pair.value)
 	}
 
-	// Default: compare as strings
-	aStr := a.ToString()
-	bStr := b.ToString()
+	return types.NewBool(true)
+}
+
+// sortsBefore reports whether a sorts before b, in descending order with
+// reverse set
+func sortsBefore(compare comparator, a, b *types.Value, reverse bool) bool {
 	if reverse {
-		return aStr < bStr
+		return compare(b, a) < 0
 	}
-	return aStr > bStr
+	return compare(a, b) < 0
 }
@@ -1,11 +1,35 @@
 package array
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
+	"github.com/krizos/php-go/pkg/stdlib"
 	"github.com/krizos/php-go/pkg/types"
 )
 
+// testCaller resolves callables by name to Go implementations
+var testCaller = stdlib.CallerFunc(func(callable *types.Value, args []*types.Value) (*types.Value, error) {
+	switch callable.ToString() {
+	case "double":
+		return types.NewInt(args[0].ToInt() * 2), nil
+	case "sum":
+		return types.NewInt(args[0].ToInt() + args[1].ToInt()), nil
+	case "is_odd":
+		return types.NewBool(args[0].ToInt()%2 == 1), nil
+	case "cmp":
+		return types.NewInt(args[0].ToInt() - args[1].ToInt()), nil
+	case "rcmp":
+		return types.NewInt(args[1].ToInt() - args[0].ToInt()), nil
+	case "strcmp":
+		return types.NewInt(int64(strings.Compare(args[0].ToString(), args[1].ToString()))), nil
+	case "fail":
+		return nil, errors.New("callback failed")
+	}
+	return nil, errors.New("unknown callable " + callable.ToString())
+})
+
 // ============================================================================
 // Count/Sizeof Tests
 // ============================================================================
@@ -204,6 +228,17 @@ func TestInArray(t *testing.T) {
 	if result.ToBool() {
 		t.Error("Expected in_array to not find value 99")
 	}
+
+	// Loose comparison finds "20", strict comparison does not
+	if !InArray(types.NewString("20"), arrVal).ToBool() {
+		t.Error("Expected in_array to find \"20\" loosely")
+	}
+	if InArray(types.NewString("20"), arrVal, types.NewBool(true)).ToBool() {
+		t.Error("Expected strict in_array not to find \"20\"")
+	}
+	if InArray(types.NewString("abc"), types.NewArray(types.NewArrayFromSlice([]*types.Value{types.NewInt(0)}))).ToBool() {
+		t.Error("Expected in_array not to find \"abc\" among 0 (PHP 8)")
+	}
 }
 
 func TestArraySearch(t *testing.T) {
@@ -223,6 +258,11 @@ func TestArraySearch(t *testing.T) {
 	if result.Type() != types.TypeBool || result.ToBool() != false {
 		t.Error("Expected array_search to return false for missing value")
 	}
+
+	mixed := types.NewArray(types.NewArrayFromSlice([]*types.Value{types.NewString("1"), types.NewInt(1)}))
+	if result = ArraySearch(types.NewInt(1), mixed, types.NewBool(true)); result.ToInt() != 1 {
+		t.Errorf("Expected strict array_search to return 1, got %v", result)
+	}
 }
 
 // ============================================================================
@@ -623,8 +663,11 @@ func TestArrayMap(t *testing.T) {
 	arr.Push(types.NewInt(1), types.NewInt(2), types.NewInt(3))
 	arrVal := types.NewArray(arr)
 
-	// For now, just test that it returns an array
-	result := ArrayMap(types.NewNull(), arrVal)
+	// A null callback returns the array unchanged
+	result, err := ArrayMap(nil, types.NewNull(), arrVal)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 	if result.Type() != types.TypeArray {
 		t.Error("Expected array_map to return an array")
 	}
@@ -640,7 +683,7 @@ func TestArrayFilter(t *testing.T) {
 	arr.Push(types.NewInt(0), types.NewInt(1), types.NewInt(0), types.NewInt(2), types.NewInt(0))
 	arrVal := types.NewArray(arr)
 
-	result := ArrayFilter(arrVal)
+	result, _ := ArrayFilter(nil, arrVal)
 	resultArray := result.ToArray()
 
 	// Should filter out false-y values (0s)
@@ -657,7 +700,7 @@ func TestArrayFilterTruthyValues(t *testing.T) {
 	arr.Set(types.NewString("d"), types.NewString(""))
 	arrVal := types.NewArray(arr)
 
-	result := ArrayFilter(arrVal)
+	result, _ := ArrayFilter(nil, arrVal)
 	resultArray := result.ToArray()
 
 	// Should keep keys 'a' and 'c' (truthy values)
@@ -684,13 +727,22 @@ func TestArrayReduce(t *testing.T) {
 	arrVal := types.NewArray(arr)
 
 	// Test with initial value
-	result := ArrayReduce(arrVal, types.NewNull(), types.NewInt(10))
-	if result.ToInt() != 10 {
-		t.Errorf("Expected initial value 10, got %d", result.ToInt())
+	result, err := ArrayReduce(testCaller, arrVal, types.NewString("sum"), types.NewInt(10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ToInt() != 16 {
+		t.Errorf("Expected 16, got %d", result.ToInt())
 	}
 
-	// Test without initial value
-	result = ArrayReduce(arrVal, types.NewNull())
+	// Test without initial value (carry starts as null)
+	result, _ = ArrayReduce(testCaller, arrVal, types.NewString("sum"))
+	if result.ToInt() != 6 {
+		t.Errorf("Expected 6, got %d", result.ToInt())
+	}
+
+	// Empty array returns the initial value without calling back
+	result, _ = ArrayReduce(nil, types.NewArray(types.NewEmptyArray()), types.NewString("sum"))
 	if result.Type() != types.TypeNull {
 		t.Error("Expected null when no initial value provided")
 	}
@@ -701,10 +753,96 @@ func TestArrayWalk(t *testing.T) {
 	arr.Push(types.NewInt(1), types.NewInt(2), types.NewInt(3))
 	arrVal := types.NewArray(arr)
 
-	result := ArrayWalk(arrVal, types.NewNull())
+	var visited []string
+	caller := stdlib.CallerFunc(func(_ *types.Value, args []*types.Value) (*types.Value, error) {
+		visited = append(visited, args[1].ToString()+"="+args[0].Deref().ToString()+args[2].ToString())
+		return types.NewNull(), nil
+	})
+
+	result, err := ArrayWalk(caller, arrVal, types.NewString("visit"), types.NewString("!"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 	if !result.ToBool() {
 		t.Error("Expected array_walk to return true")
 	}
+	if strings.Join(visited, ",") != "0=1!,1=2!,2=3!" {
+		t.Errorf("Unexpected callback arguments: %v", visited)
+	}
+}
+
+func TestArrayMapCallback(t *testing.T) {
+	arr := types.NewEmptyArray()
+	arr.Set(types.NewString("a"), types.NewInt(1))
+	arr.Set(types.NewString("b"), types.NewInt(2))
+
+	result, err := ArrayMap(testCaller, types.NewString("double"), types.NewArray(arr))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Single array keeps its keys
+	val, exists := result.ToArray().Get(types.NewString("b"))
+	if !exists || val.ToInt() != 4 {
+		t.Errorf("Expected b => 4, got %v", val)
+	}
+}
+
+func TestArrayMapMultipleArrays(t *testing.T) {
+	a := types.NewEmptyArray()
+	a.Push(types.NewInt(1), types.NewInt(2), types.NewInt(3))
+	b := types.NewEmptyArray()
+	b.Push(types.NewInt(10), types.NewInt(20))
+
+	result, err := ArrayMap(testCaller, types.NewString("sum"), types.NewArray(a), types.NewArray(b))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Shorter arrays are padded with null
+	expected := []int64{11, 22, 3}
+	for i, want := range expected {
+		val, _ := result.ToArray().Get(types.NewInt(int64(i)))
+		if val.ToInt() != want {
+			t.Errorf("Index %d: expected %d, got %d", i, want, val.ToInt())
+		}
+	}
+
+	// A null callback zips the arrays
+	zipped, _ := ArrayMap(nil, types.NewNull(), types.NewArray(a), types.NewArray(b))
+	first, _ := zipped.ToArray().Get(types.NewInt(0))
+	if first.ToArray().Len() != 2 {
+		t.Errorf("Expected zipped pairs, got %v", first)
+	}
+}
+
+func TestArrayMapCallbackError(t *testing.T) {
+	arr := types.NewEmptyArray()
+	arr.Push(types.NewInt(1))
+
+	if _, err := ArrayMap(testCaller, types.NewString("fail"), types.NewArray(arr)); err == nil {
+		t.Error("Expected callback error to propagate")
+	}
+}
+
+func TestArrayFilterCallback(t *testing.T) {
+	arr := types.NewEmptyArray()
+	arr.Push(types.NewInt(1), types.NewInt(2), types.NewInt(3), types.NewInt(4))
+	arrVal := types.NewArray(arr)
+
+	result, err := ArrayFilter(testCaller, arrVal, types.NewString("is_odd"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ToArray().Len() != 2 || !result.ToArray().HasKey(types.NewInt(2)) {
+		t.Errorf("Expected keys 0 and 2 to be kept, got %v", result)
+	}
+
+	// ARRAY_FILTER_USE_KEY passes the key
+	result, _ = ArrayFilter(testCaller, arrVal, types.NewString("is_odd"), types.NewInt(ArrayFilterUseKey))
+	if result.ToArray().Len() != 2 || !result.ToArray().HasKey(types.NewInt(1)) {
+		t.Errorf("Expected keys 1 and 3 to be kept, got %v", result)
+	}
 }
 
 // ============================================================================
@@ -972,8 +1110,98 @@ func TestArrayIntersectEmpty(t *testing.T) {
 }
 
 func TestArrayMapEmpty(t *testing.T) {
-	result := ArrayMap(types.NewNull())
+	result, _ := ArrayMap(nil, types.NewNull())
 	if result.Type() != types.TypeArray {
 		t.Error("Expected empty array for map with no arrays")
 	}
 }
+
+// ============================================================================
+// User Sort Tests
+// ============================================================================
+
+func TestUsort(t *testing.T) {
+	arr := types.NewEmptyArray()
+	arr.Set(types.NewString("x"), types.NewInt(3))
+	arr.Set(types.NewString("y"), types.NewInt(1))
+	arr.Set(types.NewString("z"), types.NewInt(2))
+
+	if _, err := Usort(testCaller, types.NewArray(arr), types.NewString("rcmp")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// usort re-indexes
+	expected := []int64{3, 2, 1}
+	for i, want := range expected {
+		val, exists := arr.Get(types.NewInt(int64(i)))
+		if !exists || val.ToInt() != want {
+			t.Errorf("Index %d: expected %d, got %v", i, want, val)
+		}
+	}
+}
+
+func TestUasort(t *testing.T) {
+	arr := types.NewEmptyArray()
+	arr.Set(types.NewString("x"), types.NewInt(3))
+	arr.Set(types.NewString("y"), types.NewInt(1))
+	arr.Set(types.NewString("z"), types.NewInt(2))
+
+	if _, err := Uasort(testCaller, types.NewArray(arr), types.NewString("cmp")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	keys := []string{}
+	arr.Each(func(key, _ *types.Value) bool {
+		keys = append(keys, key.ToString())
+		return true
+	})
+	if strings.Join(keys, ",") != "y,z,x" {
+		t.Errorf("Expected keys y,z,x, got %v", keys)
+	}
+}
+
+func TestUksort(t *testing.T) {
+	arr := types.NewEmptyArray()
+	arr.Set(types.NewString("b"), types.NewInt(1))
+	arr.Set(types.NewString("c"), types.NewInt(2))
+	arr.Set(types.NewString("a"), types.NewInt(3))
+
+	if _, err := Uksort(testCaller, types.NewArray(arr), types.NewString("strcmp")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	keys := []string{}
+	arr.Each(func(key, _ *types.Value) bool {
+		keys = append(keys, key.ToString())
+		return true
+	})
+	if strings.Join(keys, ",") != "a,b,c" {
+		t.Errorf("Expected keys a,b,c, got %v", keys)
+	}
+}
+
+func TestUsortCallbackError(t *testing.T) {
+	arr := types.NewEmptyArray()
+	arr.Push(types.NewInt(2), types.NewInt(1))
+
+	if _, err := Usort(testCaller, types.NewArray(arr), types.NewString("fail")); err == nil {
+		t.Error("Expected callback error to propagate")
+	}
+}
+
+func TestArrayUdiff(t *testing.T) {
+	arr1 := types.NewEmptyArray()
+	arr1.Push(types.NewInt(1), types.NewInt(2), types.NewInt(3), types.NewInt(4))
+	arr2 := types.NewEmptyArray()
+	arr2.Push(types.NewInt(2), types.NewInt(4))
+
+	result, err := ArrayUdiff(testCaller, types.NewArray(arr1), types.NewArray(arr2), types.NewString("cmp"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resultArray := result.ToArray()
+	if resultArray.Len() != 2 || !resultArray.HasKey(types.NewInt(0)) || !resultArray.HasKey(types.NewInt(2)) {
+		t.Errorf("Expected keys 0 and 2, got %v", result)
+	}
+}
@@ -0,0 +1,216 @@
+package array
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Array Key Functions
+// ============================================================================
+
+// ArrayKeyExists checks if a key exists in an array. Keys are normalized
+// as array subscripts are: "1" finds 1, null finds "", floats and bools
+// are truncated to ints.
+// array_key_exists(string|int|float|bool|resource|null $key, array $array): bool
+func ArrayKeyExists(key, arr *types.Value) (*types.Value, error) {
+	if arr == nil || arr.Type() != types.TypeArray {
+		return types.NewBool(false), nil
+	}
+	key, err := arrayKey(key, "array_key_exists", 1)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewBool(arr.ToArray().HasKey(key)), nil
+}
+
+// arrayKey converts a value used as an array key: null becomes "", a bool
+// or float an int. Arrays and objects cannot be keys.
+func arrayKey(key *types.Value, function string, arg int) (*types.Value, error) {
+	key = key.Deref()
+	switch {
+	case key.IsNull():
+		return types.NewString(""), nil
+	case key.IsBool(), key.IsFloat():
+		return types.NewInt(key.ToInt()), nil
+	case key.IsInt(), key.IsString():
+		return key, nil
+	}
+	return nil, &Error{Class: "TypeError", Message: fmt.Sprintf("%s(): Argument #%d ($key) must be a valid array offset type", function, arg)}
+}
+
+// ArrayFillKeys fills an array with a value, using the values of another
+// array as keys
+// array_fill_keys(array $keys, mixed $value): array
+func ArrayFillKeys(keys, value *types.Value) *types.Value {
+	result := types.NewEmptyArray()
+	if keys == nil || keys.Type() != types.TypeArray {
+		return types.NewArray(result)
+	}
+
+	keys.ToArray().Each(func(_, key *types.Value) bool {
+		key = key.Deref()
+		if !key.IsInt() {
+			key = types.NewString(key.ToString())
+		}
+		result.Set(key, value)
+		return true
+	})
+	return types.NewArray(result)
+}
+
+// ArrayDiffKey computes the difference of arrays using keys: the entries
+// of the first array whose key is in none of the others
+// array_diff_key(array $array, array ...$arrays): array
+func ArrayDiffKey(arrays ...*types.Value) *types.Value {
+	return filterByKey(arrays, false)
+}
+
+// ArrayIntersectKey computes the intersection of arrays using keys: the
+// entries of the first array whose key is in all the others
+// array_intersect_key(array $array, array ...$arrays): array
+func ArrayIntersectKey(arrays ...*types.Value) *types.Value {
+	return filterByKey(arrays, true)
+}
+
+// filterByKey keeps the entries of the first array whose key is in all
+// (intersect) or none (!intersect) of the other arrays
+func filterByKey(arrays []*types.Value, intersect bool) *types.Value {
+	result := types.NewEmptyArray()
+	if len(arrays) == 0 || arrays[0] == nil || arrays[0].Type() != types.TypeArray {
+		return types.NewArray(result)
+	}
+
+	arrays[0].ToArray().Each(func(key, value *types.Value) bool {
+		keep := true
+		for _, other := range arrays[1:] {
+			found := other != nil && other.Type() == types.TypeArray && other.ToArray().HasKey(key)
+			if found != intersect {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			result.Set(key, value)
+		}
+		return true
+	})
+	return types.NewArray(result)
+}
+
+// ArrayReplace replaces the entries of the first array with the entries
+// of the same keys of the following arrays; entries with new keys are
+// added. Unlike array_merge(), integer keys are not renumbered.
+// array_replace(array $array, array ...$replacements): array
+func ArrayReplace(arrays ...*types.Value) *types.Value {
+	result := types.NewEmptyArray()
+	for _, arr := range arrays {
+		if arr == nil || arr.Type() != types.TypeArray {
+			continue
+		}
+		arr.ToArray().Each(func(key, value *types.Value) bool {
+			result.Set(key, value)
+			return true
+		})
+	}
+	return types.NewArray(result)
+}
+
+// ArrayColumn returns the values of a column of an array of rows (arrays,
+// or objects, whose public properties are their columns). A null column
+// key returns whole rows. With an index key, results are keyed by that
+// column of each row; rows without it get the next integer key.
+// array_column(array $array, int|string|null $column_key, int|string|null $index_key = null): array
+func ArrayColumn(arr, columnKey *types.Value, indexKey ...*types.Value) *types.Value {
+	result := types.NewEmptyArray()
+	if arr == nil || arr.Type() != types.TypeArray {
+		return types.NewArray(result)
+	}
+
+	var index *types.Value
+	if len(indexKey) > 0 && indexKey[0] != nil && !indexKey[0].IsNull() {
+		index = indexKey[0]
+	}
+
+	arr.ToArray().Each(func(_, row *types.Value) bool {
+		row = row.Deref()
+		value := row
+		if columnKey != nil && !columnKey.IsNull() {
+			var ok bool
+			if value, ok = rowColumn(row, columnKey); !ok {
+				return true
+			}
+		}
+
+		if index != nil {
+			if key, ok := rowColumn(row, index); ok && (key.IsInt() || key.IsString()) {
+				result.Set(key, value)
+				return true
+			}
+		}
+		result.Append(value)
+		return true
+	})
+	return types.NewArray(result)
+}
+
+// rowColumn returns a column of a row of array_column(): an element of an
+// array or a public property of an object
+func rowColumn(row, key *types.Value) (*types.Value, bool) {
+	switch {
+	case row.IsArray():
+		value, ok := row.ToArray().Get(key)
+		return value.Deref(), ok
+	case row.IsObject():
+		prop, ok := row.ToObject().Properties[key.ToString()]
+		if !ok || prop.Visibility != types.VisibilityPublic {
+			return nil, false
+		}
+		return prop.Value.Deref(), true
+	}
+	return nil, false
+}
+
+// ArrayPad pads an array to a length with a value: at the end for a
+// positive length, at the start for a negative one. String keys are kept;
+// integer keys are renumbered.
+// array_pad(array $array, int $length, mixed $value): array
+func ArrayPad(arr, length, value *types.Value) *types.Value {
+	if arr == nil || arr.Type() != types.TypeArray {
+		return types.NewArray(types.NewEmptyArray())
+	}
+
+	source := arr.ToArray()
+	size := length.ToInt()
+	padding := size
+	if padding < 0 {
+		padding = -padding
+	}
+	padding -= int64(source.Len())
+	if padding <= 0 {
+		return types.NewArray(source.Copy())
+	}
+
+	result := types.NewEmptyArray()
+	pad := func() {
+		for i := int64(0); i < padding; i++ {
+			result.Append(value)
+		}
+	}
+	if size < 0 {
+		pad()
+	}
+	source.Each(func(key, value *types.Value) bool {
+		if key.IsString() {
+			result.Set(key, value)
+		} else {
+			result.Append(value)
+		}
+		return true
+	})
+	if size > 0 {
+		pad()
+	}
+	return types.NewArray(result)
+}
@@ -0,0 +1,113 @@
+package array
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// assocOf builds an array from alternating keys and values
+func assocOf(pairs ...interface{}) *types.Value {
+	arr := types.NewEmptyArray()
+	for i := 0; i+1 < len(pairs); i += 2 {
+		arr.Set(goValue(pairs[i]), goValue(pairs[i+1]))
+	}
+	return types.NewArray(arr)
+}
+
+func goValue(v interface{}) *types.Value {
+	switch v := v.(type) {
+	case int:
+		return types.NewInt(int64(v))
+	case string:
+		return types.NewString(v)
+	case *types.Value:
+		return v
+	}
+	return types.NewNull()
+}
+
+func TestArrayKeyExists(t *testing.T) {
+	arr := assocOf(1, "a", "", "empty", "name", nil)
+
+	tests := []struct {
+		key      *types.Value
+		expected bool
+	}{
+		{types.NewString("1"), true},
+		{types.NewFloat(1.7), true},
+		{types.NewBool(true), true},
+		{types.NewNull(), true},
+		{types.NewString("name"), true}, // null values still exist
+		{types.NewString("missing"), false},
+	}
+	for _, tt := range tests {
+		result, err := ArrayKeyExists(tt.key, arr)
+		if err != nil || result.ToBool() != tt.expected {
+			t.Errorf("array_key_exists(%v): expected %v, got %v (%v)", tt.key, tt.expected, result, err)
+		}
+	}
+
+	if _, err := ArrayKeyExists(listOf(1), arr); err == nil {
+		t.Error("Expected an array key to be a TypeError")
+	}
+}
+
+func TestArrayColumn(t *testing.T) {
+	rows := listOf()
+	rows.ToArray().Append(assocOf("id", 3, "name", "Ann"))
+	rows.ToArray().Append(assocOf("id", 5, "name", "Bob"))
+	rows.ToArray().Append(assocOf("name", "Cid"))
+
+	names := ArrayColumn(rows, types.NewString("name"))
+	if got := valueStrings(names); !equalStrings(got, []string{"Ann", "Bob", "Cid"}) {
+		t.Errorf("Expected the name column, got %v", got)
+	}
+
+	byID := ArrayColumn(rows, types.NewString("name"), types.NewString("id"))
+	if got := keyStrings(byID); !equalStrings(got, []string{"3", "5", "6"}) {
+		t.Errorf("Expected rows keyed by id, the last appended, got %v", got)
+	}
+
+	whole := ArrayColumn(rows, types.NewNull(), types.NewString("id"))
+	if row, _ := whole.ToArray().Get(types.NewInt(5)); !row.IsArray() {
+		t.Errorf("Expected a null column to return whole rows, got %v", row)
+	}
+}
+
+func TestArrayFillKeysAndPad(t *testing.T) {
+	filled := ArrayFillKeys(listOf("a", 5, "b"), types.NewInt(0))
+	if got := keyStrings(filled); !equalStrings(got, []string{"a", "5", "b"}) {
+		t.Errorf("Expected the values as keys, got %v", got)
+	}
+
+	padded := ArrayPad(assocOf("x", 1, 7, 2), types.NewInt(-4), types.NewInt(0))
+	if got := keyStrings(padded); !equalStrings(got, []string{"0", "1", "x", "2"}) {
+		t.Errorf("Expected left padding with renumbered integer keys, got %v", got)
+	}
+
+	same := ArrayPad(listOf(1, 2), types.NewInt(1), types.NewInt(0))
+	if same.ToArray().Len() != 2 {
+		t.Errorf("Expected no padding for a shorter length, got %v", valueStrings(same))
+	}
+}
+
+func TestArrayKeySetOperations(t *testing.T) {
+	base := assocOf("a", 1, "b", 2, 0, 3)
+	other := assocOf("a", 9, "0", 9)
+
+	if got := keyStrings(ArrayDiffKey(base, other)); !equalStrings(got, []string{"b"}) {
+		t.Errorf("array_diff_key: expected [b], got %v", got)
+	}
+	if got := keyStrings(ArrayIntersectKey(base, other)); !equalStrings(got, []string{"a", "0"}) {
+		t.Errorf("array_intersect_key: expected [a 0], got %v", got)
+	}
+
+	replaced := ArrayReplace(listOf("x", "y"), assocOf(1, "Y", 3, "Z"))
+	if got := valueStrings(replaced); !equalStrings(got, []string{"x", "Y", "Z"}) {
+		t.Errorf("array_replace: expected [x Y Z], got %v", got)
+	}
+	if got := keyStrings(replaced); !equalStrings(got, []string{"0", "1", "3"}) {
+		t.Errorf("array_replace: expected integer keys kept, got %v", got)
+	}
+}
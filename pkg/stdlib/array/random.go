@@ -0,0 +1,75 @@
+package array
+
+import (
+	stdmath "github.com/krizos/php-go/pkg/stdlib/math"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Random Array Functions
+// ============================================================================
+
+// Both functions draw from the Mersenne Twister of mt_rand(), so a script
+// seeding it with mt_srand() gets reproducible results.
+
+// ArrayRand picks one or more random keys out of an array: a single key
+// for num 1, otherwise an array of num keys in their original order
+// array_rand(array $array, int $num = 1): int|string|array
+func ArrayRand(arr *types.Value, num ...*types.Value) (*types.Value, error) {
+	if arr == nil || arr.Type() != types.TypeArray || arr.ToArray().Len() == 0 {
+		return nil, &Error{Class: "ValueError", Message: "array_rand(): Argument #1 ($array) cannot be empty"}
+	}
+
+	var keys []*types.Value
+	arr.ToArray().Each(func(key, _ *types.Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	n := int64(1)
+	if len(num) > 0 && num[0] != nil {
+		n = num[0].ToInt()
+	}
+	if n < 1 || n > int64(len(keys)) {
+		return nil, &Error{Class: "ValueError", Message: "array_rand(): Argument #2 ($num) must be between 1 and the number of elements in argument #1 ($array)"}
+	}
+	if n == 1 {
+		return keys[stdmath.RandRange(0, int64(len(keys)-1))], nil
+	}
+
+	// Selection sampling keeps the chosen keys in order
+	result := types.NewEmptyArray()
+	needed := n
+	for i, key := range keys {
+		remaining := int64(len(keys) - i)
+		if stdmath.RandRange(0, remaining-1) < needed {
+			result.Append(key)
+			needed--
+			if needed == 0 {
+				break
+			}
+		}
+	}
+	return types.NewArray(result), nil
+}
+
+// Shuffle randomizes the order of the values of an array, renumbering its
+// keys
+// shuffle(array &$array): true
+func Shuffle(arr *types.Value) *types.Value {
+	if arr == nil || arr.Type() != types.TypeArray {
+		return types.NewBool(false)
+	}
+
+	arrayData := arr.ToArray()
+	values := arrayValues(arrayData)
+	for left := len(values) - 1; left > 0; left-- {
+		j := stdmath.RandRange(0, int64(left))
+		values[left], values[j] = values[j], values[left]
+	}
+
+	arrayData.Reset()
+	for _, value := range values {
+		arrayData.Append(value)
+	}
+	return types.NewBool(true)
+}
@@ -0,0 +1,60 @@
+package array
+
+import (
+	"testing"
+
+	stdmath "github.com/krizos/php-go/pkg/stdlib/math"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestShuffleIsSeedable(t *testing.T) {
+	shuffled := func() []string {
+		stdmath.MtSrand(types.NewInt(42))
+		arr := assocOf("a", 1, "b", 2, "c", 3, "d", 4, "e", 5)
+		Shuffle(arr)
+		if got := keyStrings(arr); !equalStrings(got, []string{"0", "1", "2", "3", "4"}) {
+			t.Errorf("Expected shuffle to renumber keys, got %v", got)
+		}
+		return valueStrings(arr)
+	}
+
+	first, second := shuffled(), shuffled()
+	if !equalStrings(first, second) {
+		t.Errorf("Expected the same seed to give the same order, got %v and %v", first, second)
+	}
+}
+
+func TestArrayRand(t *testing.T) {
+	arr := assocOf("a", 1, "b", 2, "c", 3, "d", 4)
+
+	key, err := ArrayRand(arr)
+	if err != nil || !arr.ToArray().HasKey(key) {
+		t.Errorf("Expected a key of the array, got %v (%v)", key, err)
+	}
+
+	keys, err := ArrayRand(arr, types.NewInt(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	picked := valueStrings(keys)
+	if len(picked) != 3 {
+		t.Fatalf("Expected 3 keys, got %v", picked)
+	}
+	order := map[string]int{"a": 0, "b": 1, "c": 2, "d": 3}
+	for i := 1; i < len(picked); i++ {
+		if order[picked[i-1]] >= order[picked[i]] {
+			t.Errorf("Expected the keys in their original order, got %v", picked)
+		}
+	}
+
+	all, _ := ArrayRand(arr, types.NewInt(4))
+	if got := valueStrings(all); !equalStrings(got, []string{"a", "b", "c", "d"}) {
+		t.Errorf("Expected all keys, got %v", got)
+	}
+
+	for _, args := range [][]*types.Value{{listOf()}, {arr, types.NewInt(0)}, {arr, types.NewInt(5)}} {
+		if _, err := ArrayRand(args[0], args[1:]...); err == nil {
+			t.Errorf("Expected a ValueError for %v", args)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package array
+
+import (
+	"math"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Ranges and Array Arithmetic
+// ============================================================================
+
+// Range creates an array containing a range of elements. Integer bounds
+// and steps give ints, a float bound or fractional step gives floats, and
+// two single-character non-numeric strings give a range of characters.
+// The step is taken in the direction of the range; a negative step for an
+// increasing range or a zero step is a ValueError. A step larger than
+// the range gives just the start.
+// range(string|int|float $start, string|int|float $end, int|float $step = 1): array
+func Range(start, end *types.Value, step ...*types.Value) (*types.Value, error) {
+	start, end = start.Deref(), end.Deref()
+	stepValue := types.NewInt(1)
+	if len(step) > 0 && step[0] != nil {
+		stepValue = rangeNumber(step[0].Deref())
+	}
+
+	s := stepValue.ToFloat()
+	if s == 0 {
+		return nil, &Error{Class: "ValueError", Message: "range(): Argument #3 ($step) cannot be 0"}
+	}
+	if math.IsNaN(s) || math.IsInf(s, 0) {
+		return nil, &Error{Class: "ValueError", Message: "range(): Argument #3 ($step) must be a finite number, INF provided"}
+	}
+
+	if low, high, ok := rangeChars(start, end); ok && stepValue.IsInt() {
+		if s < 0 && low < high {
+			return nil, &Error{Class: "ValueError", Message: "range(): Argument #3 ($step) must be greater than 0 for increasing ranges"}
+		}
+		result := types.NewEmptyArray()
+		stride := int(math.Abs(s))
+		if low <= high {
+			for c := int(low); c <= int(high); c += stride {
+				result.Append(types.NewString(string([]byte{byte(c)})))
+			}
+		} else {
+			for c := int(low); c >= int(high); c -= stride {
+				result.Append(types.NewString(string([]byte{byte(c)})))
+			}
+		}
+		return types.NewArray(result), nil
+	}
+
+	from, to := rangeNumber(start), rangeNumber(end)
+	if s < 0 && from.ToFloat() < to.ToFloat() {
+		return nil, &Error{Class: "ValueError", Message: "range(): Argument #3 ($step) must be greater than 0 for increasing ranges"}
+	}
+	s = math.Abs(s)
+
+	result := types.NewEmptyArray()
+	if from.IsInt() && to.IsInt() && s == math.Trunc(s) {
+		low, high, stride := from.ToInt(), to.ToInt(), int64(s)
+		if low <= high {
+			for i := low; i <= high && i >= low; i += stride {
+				result.Append(types.NewInt(i))
+			}
+		} else {
+			for i := low; i >= high && i <= low; i -= stride {
+				result.Append(types.NewInt(i))
+			}
+		}
+		return types.NewArray(result), nil
+	}
+
+	low, high := from.ToFloat(), to.ToFloat()
+	count := int64(math.Floor(math.Abs(high-low)/s + 1e-9))
+	for i := int64(0); i <= count; i++ {
+		if low <= high {
+			result.Append(types.NewFloat(low + float64(i)*s))
+		} else {
+			result.Append(types.NewFloat(low - float64(i)*s))
+		}
+	}
+	return types.NewArray(result), nil
+}
+
+// rangeChars returns the characters of a character range: both bounds
+// are strings of one non-digit byte
+func rangeChars(start, end *types.Value) (byte, byte, bool) {
+	if !start.IsString() || !end.IsString() {
+		return 0, 0, false
+	}
+	a, b := start.ToString(), end.ToString()
+	if len(a) != 1 || len(b) != 1 || isDigit(a[0]) || isDigit(b[0]) {
+		return 0, 0, false
+	}
+	return a[0], b[0], true
+}
+
+// rangeNumber converts a bound or step of range() to an int or float
+func rangeNumber(v *types.Value) *types.Value {
+	switch {
+	case v.IsInt(), v.IsFloat():
+		return v
+	case v.IsString():
+		n, _ := types.ParseNumeric(v.ToString())
+		return n
+	}
+	return types.NewInt(v.ToInt())
+}
+
+// ArraySum returns the sum of the values of an array. Ints overflowing
+// the integer range give a float; arrays and objects are skipped.
+// array_sum(array $array): int|float
+func ArraySum(arr *types.Value) *types.Value {
+	return foldNumbers(arr, types.NewInt(0), types.Add)
+}
+
+// ArrayProduct returns the product of the values of an array (1 for an
+// empty array)
+// array_product(array $array): int|float
+func ArrayProduct(arr *types.Value) *types.Value {
+	return foldNumbers(arr, types.NewInt(1), types.Mul)
+}
+
+// foldNumbers combines the numeric values of an array
+func foldNumbers(arr, initial *types.Value, combine func(a, b *types.Value) *types.Value) *types.Value {
+	result := initial
+	if arr == nil || arr.Type() != types.TypeArray {
+		return result
+	}
+	arr.ToArray().Each(func(_, value *types.Value) bool {
+		value = value.Deref()
+		if value.IsArray() || value.IsObject() {
+			return true
+		}
+		result = combine(result, rangeNumber(value))
+		return true
+	})
+	return result
+}
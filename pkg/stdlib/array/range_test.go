@@ -0,0 +1,81 @@
+package array
+
+import (
+	"math"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		start, end interface{}
+		step       *types.Value
+		expected   []string
+	}{
+		{1, 5, nil, []string{"1", "2", "3", "4", "5"}},
+		{5, 1, types.NewInt(2), []string{"5", "3", "1"}},
+		{5, 1, types.NewInt(-2), []string{"5", "3", "1"}},
+		{0, 1, types.NewFloat(0.25), []string{"0", "0.25", "0.5", "0.75", "1"}},
+		{"a", "e", types.NewInt(2), []string{"a", "c", "e"}},
+		{"z", "w", nil, []string{"z", "y", "x", "w"}},
+		{"1", "3", nil, []string{"1", "2", "3"}},
+		{1, 2, types.NewInt(5), []string{"1"}},
+	}
+
+	for _, tt := range tests {
+		var step []*types.Value
+		if tt.step != nil {
+			step = append(step, tt.step)
+		}
+		result, err := Range(goValue(tt.start), goValue(tt.end), step...)
+		if err != nil {
+			t.Errorf("range(%v, %v): unexpected error: %v", tt.start, tt.end, err)
+			continue
+		}
+		if got := valueStrings(result); !equalStrings(got, tt.expected) {
+			t.Errorf("range(%v, %v, %v): expected %v, got %v", tt.start, tt.end, tt.step, tt.expected, got)
+		}
+	}
+
+	result, _ := Range(types.NewInt(1), types.NewInt(2), types.NewFloat(0.5))
+	if first, _ := result.ToArray().Get(types.NewInt(0)); !first.IsFloat() {
+		t.Error("Expected a fractional step to give floats")
+	}
+}
+
+func TestRangeErrors(t *testing.T) {
+	tests := []struct {
+		step    *types.Value
+		message string
+	}{
+		{types.NewInt(0), "range(): Argument #3 ($step) cannot be 0"},
+		{types.NewInt(-1), "range(): Argument #3 ($step) must be greater than 0 for increasing ranges"},
+	}
+	for _, tt := range tests {
+		_, err := Range(types.NewInt(1), types.NewInt(3), tt.step)
+		if e, ok := err.(*Error); !ok || e.Class != "ValueError" || e.Message != tt.message {
+			t.Errorf("Expected ValueError %q, got %v", tt.message, err)
+		}
+	}
+}
+
+func TestArraySumAndProduct(t *testing.T) {
+	values := listOf(1, "2", "3.5", true, nil)
+	if sum := ArraySum(values); !sum.IsFloat() || sum.ToFloat() != 7.5 {
+		t.Errorf("Expected 7.5, got %v", sum)
+	}
+	if product := ArrayProduct(listOf(2, "3", 4)); !product.IsInt() || product.ToInt() != 24 {
+		t.Errorf("Expected 24, got %v", product)
+	}
+	if product := ArrayProduct(listOf()); product.ToInt() != 1 {
+		t.Errorf("Expected the product of an empty array to be 1, got %v", product)
+	}
+
+	big := types.NewArray(types.NewEmptyArray())
+	big.ToArray().Append(types.NewInt(math.MaxInt64))
+	big.ToArray().Append(types.NewInt(1))
+	if sum := ArraySum(big); !sum.IsFloat() {
+		t.Errorf("Expected an overflowing sum to be a float, got %v", sum)
+	}
+}
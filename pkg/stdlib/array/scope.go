@@ -0,0 +1,164 @@
+package array
+
+import (
+	"github.com/krizos/php-go/pkg/stdlib"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Symbol Table Functions
+// ============================================================================
+
+// Flags of extract()
+const (
+	ExtrOverwrite      = 0   // Overwrite existing variables
+	ExtrSkip           = 1   // Keep existing variables
+	ExtrPrefixSame     = 2   // Prefix the names of existing variables
+	ExtrPrefixAll      = 3   // Prefix all names
+	ExtrPrefixInvalid  = 4   // Prefix invalid and numeric names
+	ExtrPrefixIfExists = 5   // Create prefixed variables for existing ones only
+	ExtrIfExists       = 6   // Overwrite existing variables only
+	ExtrRefs           = 256 // Combined with a mode: extract references
+	extrModeMask       = 0xff
+)
+
+// Compact creates an array of the variables named by its arguments, which
+// may be names or arrays of names (nested to any depth). Undefined
+// variables are skipped with a warning.
+// compact(array|string $var_name, array|string ...$var_names): array
+func Compact(scope stdlib.Scope, names ...*types.Value) *types.Value {
+	result := types.NewEmptyArray()
+	var collect func(name *types.Value)
+	collect = func(name *types.Value) {
+		name = name.Deref()
+		if name.IsArray() {
+			name.ToArray().Each(func(_, nested *types.Value) bool {
+				collect(nested)
+				return true
+			})
+			return
+		}
+		if value, ok := scope.LookupVariable(name.ToString()); ok {
+			result.Set(types.NewString(name.ToString()), value.Deref())
+		} else {
+			scope.Warning("compact(): Undefined variable $" + name.ToString())
+		}
+	}
+	for _, name := range names {
+		collect(name)
+	}
+	return types.NewArray(result)
+}
+
+// Extract imports the entries of an array into the calling scope as
+// variables, following the collision mode of flags. Keys that are not
+// valid variable names are skipped, unless a prefix makes them valid.
+// It returns the number of variables imported.
+// extract(array &$array, int $flags = EXTR_OVERWRITE, string $prefix = ""): int
+func Extract(scope stdlib.Scope, arr *types.Value, args ...*types.Value) (*types.Value, error) {
+	flags := int64(ExtrOverwrite)
+	if len(args) > 0 && args[0] != nil {
+		flags = args[0].ToInt()
+	}
+	prefix := ""
+	if len(args) > 1 && args[1] != nil {
+		prefix = args[1].ToString()
+	}
+
+	mode := flags & extrModeMask
+	if mode < ExtrOverwrite || mode > ExtrIfExists || flags&^(extrModeMask|ExtrRefs) != 0 {
+		return nil, &Error{Class: "ValueError", Message: "extract(): Argument #2 ($flags) must be a valid extract type"}
+	}
+	if mode >= ExtrPrefixSame && mode <= ExtrPrefixIfExists && len(args) < 2 {
+		return nil, &Error{Class: "ValueError", Message: "extract(): Argument #3 ($prefix) is required when using this extract type"}
+	}
+	if prefix != "" && !validVariableName(prefix) {
+		return nil, &Error{Class: "ValueError", Message: "extract(): Argument #3 ($prefix) must be a valid identifier"}
+	}
+	if arr == nil || arr.Type() != types.TypeArray {
+		return types.NewInt(0), nil
+	}
+
+	var pairs []struct{ key, value *types.Value }
+	arr.ToArray().Each(func(key, value *types.Value) bool {
+		pairs = append(pairs, struct{ key, value *types.Value }{key, value})
+		return true
+	})
+
+	count := int64(0)
+	for _, pair := range pairs {
+		name, ok := extractName(scope, pair.key, mode, prefix)
+		if !ok {
+			continue
+		}
+
+		var err error
+		if flags&ExtrRefs != 0 {
+			ref := pair.value
+			if !ref.IsReference() {
+				ref = types.NewReference(ref)
+				arr.ToArray().Set(pair.key, ref)
+			}
+			err = scope.BindVariable(name, ref)
+		} else {
+			err = scope.AssignVariable(name, pair.value.Deref())
+		}
+		if err != nil {
+			return nil, err
+		}
+		count++
+	}
+	return types.NewInt(count), nil
+}
+
+// extractName returns the name of the variable extract() imports an
+// entry as under a collision mode; false if the entry is skipped
+func extractName(scope stdlib.Scope, key *types.Value, mode int64, prefix string) (string, bool) {
+	name := key.ToString()
+	exists := false
+	if !key.IsInt() {
+		_, exists = scope.LookupVariable(name)
+	}
+
+	switch mode {
+	case ExtrSkip:
+		if exists {
+			return "", false
+		}
+	case ExtrIfExists:
+		if !exists {
+			return "", false
+		}
+	case ExtrPrefixSame:
+		if exists {
+			name = prefix + "_" + name
+		}
+	case ExtrPrefixAll:
+		name = prefix + "_" + name
+	case ExtrPrefixInvalid:
+		if key.IsInt() || !validVariableName(name) {
+			name = prefix + "_" + name
+		}
+	case ExtrPrefixIfExists:
+		if !exists {
+			return "", false
+		}
+		name = prefix + "_" + name
+	}
+	return name, validVariableName(name) && name != "GLOBALS"
+}
+
+// validVariableName reports whether a string is a valid variable name
+func validVariableName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		letter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+		if !letter && (i == 0 || !isDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
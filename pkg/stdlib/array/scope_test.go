@@ -0,0 +1,113 @@
+package array
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// testScope is a symbol table standing in for the calling scope
+type testScope struct {
+	vars     map[string]*types.Value
+	warnings []string
+}
+
+func newTestScope() *testScope {
+	return &testScope{vars: map[string]*types.Value{}}
+}
+
+func (s *testScope) LookupVariable(name string) (*types.Value, bool) {
+	value, ok := s.vars[name]
+	return value, ok
+}
+
+func (s *testScope) AssignVariable(name string, value *types.Value) error {
+	s.vars[name] = value
+	return nil
+}
+
+func (s *testScope) BindVariable(name string, ref *types.Value) error {
+	s.vars[name] = ref
+	return nil
+}
+
+func (s *testScope) Warning(message string) {
+	s.warnings = append(s.warnings, message)
+}
+
+func TestCompact(t *testing.T) {
+	scope := newTestScope()
+	scope.vars["city"] = types.NewString("Oslo")
+	scope.vars["zip"] = types.NewInt(150)
+
+	nested := listOf("zip", "missing")
+	result := Compact(scope, types.NewString("city"), nested)
+	if got := keyStrings(result); !equalStrings(got, []string{"city", "zip"}) {
+		t.Errorf("Expected city and zip, got %v", got)
+	}
+	if len(scope.warnings) != 1 || scope.warnings[0] != "compact(): Undefined variable $missing" {
+		t.Errorf("Expected an undefined variable warning, got %v", scope.warnings)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		flags    int64
+		prefix   string
+		expected []string // variables defined afterwards, besides $a
+		count    int64
+	}{
+		{ExtrOverwrite, "", []string{"a=new", "b=2"}, 2},
+		{ExtrSkip, "", []string{"a=old", "b=2"}, 1},
+		{ExtrPrefixSame, "p", []string{"a=old", "p_a=new", "b=2"}, 2},
+		{ExtrPrefixAll, "p", []string{"a=old", "p_a=new", "p_b=2", "p_0=x"}, 3},
+		{ExtrIfExists, "", []string{"a=new"}, 1},
+		{ExtrPrefixIfExists, "p", []string{"a=old", "p_a=new"}, 1},
+	}
+
+	for _, tt := range tests {
+		scope := newTestScope()
+		scope.vars["a"] = types.NewString("old")
+		arr := assocOf("a", "new", "b", 2, 0, "x", "bad name", 1)
+
+		args := []*types.Value{types.NewInt(tt.flags)}
+		if tt.prefix != "" {
+			args = append(args, types.NewString(tt.prefix))
+		}
+		count, err := Extract(scope, arr, args...)
+		if err != nil {
+			t.Fatalf("flags %d: unexpected error: %v", tt.flags, err)
+		}
+		if count.ToInt() != tt.count {
+			t.Errorf("flags %d: expected %d variables, got %d", tt.flags, tt.count, count.ToInt())
+		}
+		if len(scope.vars) != len(tt.expected) {
+			t.Errorf("flags %d: expected %v, got %v", tt.flags, tt.expected, scope.vars)
+		}
+		for _, want := range tt.expected {
+			name, value, _ := strings.Cut(want, "=")
+			if got, ok := scope.vars[name]; !ok || got.ToString() != value {
+				t.Errorf("flags %d: expected $%s = %s, got %v", tt.flags, name, value, got)
+			}
+		}
+	}
+}
+
+func TestExtractRefsAndErrors(t *testing.T) {
+	scope := newTestScope()
+	arr := assocOf("v", 1)
+	if _, err := Extract(scope, arr, types.NewInt(ExtrRefs)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	scope.vars["v"].Assign(types.NewInt(2))
+	if value, _ := arr.ToArray().Get(types.NewString("v")); value.Deref().ToInt() != 2 {
+		t.Errorf("Expected EXTR_REFS to bind the variable to the element, got %v", value)
+	}
+
+	for _, args := range [][]*types.Value{{types.NewInt(99)}, {types.NewInt(ExtrPrefixAll)}} {
+		if _, err := Extract(newTestScope(), arr, args...); err == nil {
+			t.Errorf("Expected a ValueError for flags %v", args[0])
+		}
+	}
+}
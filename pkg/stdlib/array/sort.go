@@ -0,0 +1,292 @@
+package array
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Sort Flags
+// ============================================================================
+
+// Sort flags of sort(), asort(), ksort() and their reverse variants
+const (
+	SortRegular      = 0 // Compare items normally
+	SortNumeric      = 1 // Compare items numerically
+	SortString       = 2 // Compare items as strings
+	SortLocaleString = 5 // Compare items as strings in the current locale
+	SortNatural      = 6 // Compare items as strings using natural ordering
+	SortFlagCase     = 8 // Combined with SortString or SortNatural: case-insensitive
+	sortTypeMask     = ^SortFlagCase
+	SortAsc          = 4 // array_multisort() ascending order
+	SortDesc         = 3 // array_multisort() descending order
+)
+
+// Constants returns the sort and extract() constants defined by PHP
+func Constants() map[string]*types.Value {
+	return map[string]*types.Value{
+		"EXTR_OVERWRITE":        types.NewInt(ExtrOverwrite),
+		"EXTR_SKIP":             types.NewInt(ExtrSkip),
+		"EXTR_PREFIX_SAME":      types.NewInt(ExtrPrefixSame),
+		"EXTR_PREFIX_ALL":       types.NewInt(ExtrPrefixAll),
+		"EXTR_PREFIX_INVALID":   types.NewInt(ExtrPrefixInvalid),
+		"EXTR_PREFIX_IF_EXISTS": types.NewInt(ExtrPrefixIfExists),
+		"EXTR_IF_EXISTS":        types.NewInt(ExtrIfExists),
+		"EXTR_REFS":             types.NewInt(ExtrRefs),
+
+		"SORT_REGULAR":       types.NewInt(SortRegular),
+		"SORT_NUMERIC":       types.NewInt(SortNumeric),
+		"SORT_STRING":        types.NewInt(SortString),
+		"SORT_LOCALE_STRING": types.NewInt(SortLocaleString),
+		"SORT_NATURAL":       types.NewInt(SortNatural),
+		"SORT_FLAG_CASE":     types.NewInt(SortFlagCase),
+		"SORT_ASC":           types.NewInt(SortAsc),
+		"SORT_DESC":          types.NewInt(SortDesc),
+	}
+}
+
+// Error is an error thrown by an array function, such as the ValueError
+// of array_multisort() for arrays of different sizes
+type Error struct {
+	Class   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// ============================================================================
+// Comparisons
+// ============================================================================
+
+// comparator orders two values: negative if a sorts first, positive if b
+// does, zero if they are equal
+type comparator func(a, b *types.Value) int
+
+// sortComparator returns the comparison selected by the optional flags
+// argument of a sort function
+func sortComparator(flags []*types.Value) comparator {
+	mode := int64(SortRegular)
+	if len(flags) > 0 && flags[0] != nil {
+		mode = flags[0].ToInt()
+	}
+	return flagComparator(mode)
+}
+
+// flagComparator returns the comparison of a combination of sort flags
+func flagComparator(mode int64) comparator {
+	fold := mode&SortFlagCase != 0
+	switch mode & sortTypeMask {
+	case SortNumeric:
+		return compareNumeric
+	case SortString, SortLocaleString:
+		return func(a, b *types.Value) int { return compareStrings(a.ToString(), b.ToString(), fold) }
+	case SortNatural:
+		return func(a, b *types.Value) int { return NaturalCompare(a.ToString(), b.ToString(), fold) }
+	}
+	return CompareRegular
+}
+
+// CompareRegular compares two values the way PHP 8's comparison operators
+// do (SORT_REGULAR), see types.Compare
+func CompareRegular(a, b *types.Value) int {
+	return types.Compare(a, b)
+}
+
+// compareNumeric compares two values as numbers (SORT_NUMERIC)
+func compareNumeric(a, b *types.Value) int {
+	return compareNumbers(a.ToFloat(), b.ToFloat())
+}
+
+func compareNumbers(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareStrings compares two strings byte-wise, optionally ignoring case
+func compareStrings(a, b string, fold bool) int {
+	if fold {
+		a, b = strings.ToLower(a), strings.ToLower(b)
+	}
+	return strings.Compare(a, b)
+}
+
+// NaturalCompare compares two strings in natural order, as strnatcmp()
+// and strnatcasecmp() (fold set) do: runs of digits are compared as
+// numbers, so "img12" sorts after "img2". Runs with a leading zero are
+// compared digit by digit, as fractional parts; leading whitespace is
+// ignored.
+func NaturalCompare(a, b string, fold bool) int {
+	if fold {
+		a, b = strings.ToLower(a), strings.ToLower(b)
+	}
+
+	i, j := 0, 0
+	for {
+		for i < len(a) && isSpace(a[i]) {
+			i++
+		}
+		for j < len(b) && isSpace(b[j]) {
+			j++
+		}
+		if i >= len(a) || j >= len(b) {
+			break
+		}
+
+		if isDigit(a[i]) && isDigit(b[j]) {
+			startA, startB := i, j
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+			runA, runB := a[startA:i], b[startB:j]
+			if runA[0] != '0' && runB[0] != '0' && len(runA) != len(runB) {
+				return compareNumbers(float64(len(runA)), float64(len(runB)))
+			}
+			if c := strings.Compare(runA, runB); c != 0 {
+				return c
+			}
+			continue
+		}
+
+		if a[i] != b[j] {
+			return compareNumbers(float64(a[i]), float64(b[j]))
+		}
+		i++
+		j++
+	}
+
+	return compareNumbers(float64(len(a)-i), float64(len(b)-j))
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// ============================================================================
+// Natural Order and Multiple Array Sorting
+// ============================================================================
+
+// Natsort sorts an array by values in natural order, preserving keys
+// natsort(array &$array): true
+func Natsort(arr *types.Value) *types.Value {
+	return sortByValue(arr, func(a, b *types.Value) int {
+		return NaturalCompare(a.ToString(), b.ToString(), false)
+	}, false)
+}
+
+// Natcasesort sorts an array by values in case-insensitive natural order,
+// preserving keys
+// natcasesort(array &$array): true
+func Natcasesort(arr *types.Value) *types.Value {
+	return sortByValue(arr, func(a, b *types.Value) int {
+		return NaturalCompare(a.ToString(), b.ToString(), true)
+	}, false)
+}
+
+// multisortColumn is an array of array_multisort() with its sort order
+// and flags
+type multisortColumn struct {
+	pairs   []struct{ key, value *types.Value }
+	data    *types.Array
+	compare comparator
+	reverse bool
+}
+
+// ArrayMultisort sorts several arrays at once, or a multi-dimensional
+// array by its columns: the first array is sorted and the others are
+// reordered like it, with later arrays ordering the entries the earlier
+// ones consider equal. Each array may be followed by SORT_ASC or
+// SORT_DESC and by sort flags. String keys are kept; integer keys are
+// renumbered.
+// array_multisort(array &$array1, mixed $array1_sort_order = SORT_ASC, mixed $array1_sort_flags = SORT_REGULAR, mixed ...$rest): bool
+func ArrayMultisort(args ...*types.Value) (*types.Value, error) {
+	var columns []*multisortColumn
+	orderSet, flagsSet := false, false
+
+	for i, arg := range args {
+		arg = arg.Deref()
+		if arg.IsArray() {
+			column := &multisortColumn{data: arg.ToArray(), compare: CompareRegular}
+			arg.ToArray().Each(func(key, value *types.Value) bool {
+				column.pairs = append(column.pairs, struct{ key, value *types.Value }{key, value})
+				return true
+			})
+			columns = append(columns, column)
+			orderSet, flagsSet = false, false
+			continue
+		}
+
+		if !arg.IsInt() || len(columns) == 0 {
+			return nil, &Error{Class: "TypeError", Message: fmt.Sprintf("array_multisort(): Argument #%d must be an array or a sort flag", i+1)}
+		}
+		current := columns[len(columns)-1]
+		switch flag := arg.ToInt(); {
+		case flag == SortAsc || flag == SortDesc:
+			if orderSet {
+				return nil, &Error{Class: "ValueError", Message: fmt.Sprintf("array_multisort(): Argument #%d must be an array or a sort flag that has not already been specified", i+1)}
+			}
+			current.reverse, orderSet = flag == SortDesc, true
+		default:
+			if flagsSet {
+				return nil, &Error{Class: "ValueError", Message: fmt.Sprintf("array_multisort(): Argument #%d must be an array or a sort flag that has not already been specified", i+1)}
+			}
+			current.compare, flagsSet = flagComparator(flag), true
+		}
+	}
+	if len(columns) == 0 {
+		return types.NewBool(true), nil
+	}
+
+	size := len(columns[0].pairs)
+	for _, column := range columns[1:] {
+		if len(column.pairs) != size {
+			return nil, &Error{Class: "ValueError", Message: "Array sizes are inconsistent"}
+		}
+	}
+
+	rows := make([]int, size)
+	for i := range rows {
+		rows[i] = i
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, column := range columns {
+			c := column.compare(column.pairs[rows[i]].value, column.pairs[rows[j]].value)
+			if column.reverse {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	for _, column := range columns {
+		column.data.Reset()
+		for _, row := range rows {
+			pair := column.pairs[row]
+			if pair.key.IsString() {
+				column.data.Set(pair.key, pair.value)
+			} else {
+				column.data.Append(pair.value)
+			}
+		}
+	}
+	return types.NewBool(true), nil
+}
@@ -0,0 +1,186 @@
+package array
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// listOf builds a list from Go values
+func listOf(values ...interface{}) *types.Value {
+	arr := types.NewEmptyArray()
+	for _, v := range values {
+		switch v := v.(type) {
+		case int:
+			arr.Append(types.NewInt(int64(v)))
+		case string:
+			arr.Append(types.NewString(v))
+		case bool:
+			arr.Append(types.NewBool(v))
+		case nil:
+			arr.Append(types.NewNull())
+		}
+	}
+	return types.NewArray(arr)
+}
+
+// valueStrings returns the values of an array as strings, in order
+func valueStrings(arr *types.Value) []string {
+	var result []string
+	arr.ToArray().Each(func(_, value *types.Value) bool {
+		result = append(result, value.ToString())
+		return true
+	})
+	return result
+}
+
+func keyStrings(arr *types.Value) []string {
+	var result []string
+	arr.ToArray().Each(func(key, _ *types.Value) bool {
+		result = append(result, key.ToString())
+		return true
+	})
+	return result
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSortFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    *types.Value
+		flags    int64
+		expected []string
+	}{
+		{"regular numeric strings", listOf("10", "9", "2", "1"), SortRegular, []string{"1", "2", "9", "10"}},
+		{"regular mixed", listOf("10", 9, "abc", 1), SortRegular, []string{"1", "9", "10", "abc"}},
+		{"string", listOf("10", "9", "2", "1"), SortString, []string{"1", "10", "2", "9"}},
+		{"numeric", listOf("3 apples", "10", "2"), SortNumeric, []string{"2", "3 apples", "10"}},
+		{"string case", listOf("b", "A", "c"), SortString, []string{"A", "b", "c"}},
+		{"string fold", listOf("b", "A", "c"), SortString | SortFlagCase, []string{"A", "b", "c"}},
+		{"natural", listOf("img12", "img10", "img2", "IMG1"), SortNatural, []string{"IMG1", "img2", "img10", "img12"}},
+		{"natural fold", listOf("img12", "IMG3", "img2"), SortNatural | SortFlagCase, []string{"img2", "IMG3", "img12"}},
+	}
+
+	for _, tt := range tests {
+		Sort(tt.input, types.NewInt(tt.flags))
+		if got := valueStrings(tt.input); !equalStrings(got, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestSortIsStable(t *testing.T) {
+	arr := types.NewEmptyArray()
+	arr.Set(types.NewString("b"), types.NewInt(1))
+	arr.Set(types.NewString("a"), types.NewInt(0))
+	arr.Set(types.NewString("c"), types.NewInt(1))
+	arr.Set(types.NewString("d"), types.NewInt(0))
+
+	Asort(types.NewArray(arr))
+	if got := keyStrings(types.NewArray(arr)); !equalStrings(got, []string{"a", "d", "b", "c"}) {
+		t.Errorf("Expected equal values to keep their order, got %v", got)
+	}
+
+	Arsort(types.NewArray(arr))
+	if got := keyStrings(types.NewArray(arr)); !equalStrings(got, []string{"b", "c", "a", "d"}) {
+		t.Errorf("Expected equal values to keep their order in reverse, got %v", got)
+	}
+}
+
+func TestNaturalCompare(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		fold     bool
+		expected int
+	}{
+		{"img2", "img10", false, -1},
+		{"img10", "img2", false, 1},
+		{"img2", "img2", false, 0},
+		{"x01", "x001", false, 1},
+		{"1.010", "1.02", false, -1},
+		{"  abc", "abc", false, 0},
+		{"ABC", "abc", false, -1},
+		{"ABC", "abc", true, 0},
+		{"a", "ab", false, -1},
+	}
+
+	for _, tt := range tests {
+		if got := NaturalCompare(tt.a, tt.b, tt.fold); got != tt.expected {
+			t.Errorf("NaturalCompare(%q, %q, %v): expected %d, got %d", tt.a, tt.b, tt.fold, tt.expected, got)
+		}
+	}
+}
+
+func TestNatsort(t *testing.T) {
+	arr := listOf("img12.png", "img10.png", "IMG2.png", "img1.png")
+
+	Natsort(arr)
+	if got := keyStrings(arr); !equalStrings(got, []string{"2", "3", "1", "0"}) {
+		t.Errorf("Expected natsort to keep keys, got %v", got)
+	}
+
+	Natcasesort(arr)
+	if got := valueStrings(arr); !equalStrings(got, []string{"img1.png", "IMG2.png", "img10.png", "img12.png"}) {
+		t.Errorf("Expected case-insensitive natural order, got %v", got)
+	}
+}
+
+func TestArrayMultisort(t *testing.T) {
+	data := listOf(3, 1, 3, 2)
+	names := listOf("c", "a", "b", "d")
+
+	if _, err := ArrayMultisort(data, types.NewInt(SortDesc), names); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := valueStrings(data); !equalStrings(got, []string{"3", "3", "2", "1"}) {
+		t.Errorf("Expected the first array in descending order, got %v", got)
+	}
+	if got := valueStrings(names); !equalStrings(got, []string{"b", "c", "d", "a"}) {
+		t.Errorf("Expected ties broken by the second array, got %v", got)
+	}
+}
+
+func TestArrayMultisortKeys(t *testing.T) {
+	arr := types.NewEmptyArray()
+	arr.Set(types.NewString("x"), types.NewInt(2))
+	arr.Set(types.NewInt(10), types.NewInt(1))
+
+	if _, err := ArrayMultisort(types.NewArray(arr)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := keyStrings(types.NewArray(arr)); !equalStrings(got, []string{"0", "x"}) {
+		t.Errorf("Expected integer keys renumbered and string keys kept, got %v", got)
+	}
+}
+
+func TestArrayMultisortErrors(t *testing.T) {
+	tests := []struct {
+		args    []*types.Value
+		class   string
+		message string
+	}{
+		{[]*types.Value{listOf(1, 2), listOf(1)}, "ValueError", "Array sizes are inconsistent"},
+		{[]*types.Value{listOf(1), types.NewInt(SortAsc), types.NewInt(SortDesc)}, "ValueError",
+			"array_multisort(): Argument #3 must be an array or a sort flag that has not already been specified"},
+		{[]*types.Value{types.NewString("x")}, "TypeError", "array_multisort(): Argument #1 must be an array or a sort flag"},
+	}
+
+	for _, tt := range tests {
+		_, err := ArrayMultisort(tt.args...)
+		e, ok := err.(*Error)
+		if !ok || e.Class != tt.class || e.Message != tt.message {
+			t.Errorf("Expected %s %q, got %v", tt.class, tt.message, err)
+		}
+	}
+}
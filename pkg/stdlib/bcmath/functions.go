@@ -0,0 +1,320 @@
+// Package bcmath implements PHP's BCMath functions: arbitrary precision
+// arithmetic on decimal numbers given as strings, for the code that cannot
+// afford the rounding of floats or the overflow of integers.
+package bcmath
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// Error is an error thrown by a BCMath function, such as the ValueError
+// of a number that is not well-formed. Class names the PHP exception.
+type Error struct {
+	Class   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func valueError(format string, args ...interface{}) error {
+	return &Error{Class: "ValueError", Message: fmt.Sprintf(format, args...)}
+}
+
+// Calculator runs the BCMath functions with a default scale, the number
+// of decimals of the results of the calls without a scale argument
+// (bcscale(), the bcmath.scale directive)
+type Calculator struct {
+	Scale int64
+}
+
+// ============================================================================
+// Arithmetic Functions
+// ============================================================================
+
+// Add adds two numbers
+// bcadd(string $num1, string $num2, ?int $scale = null): string
+func (c *Calculator) Add(num1, num2 *types.Value, scale ...*types.Value) (*types.Value, error) {
+	a, b, s, err := c.binaryArguments("bcadd", num1, num2, scale)
+	if err != nil {
+		return nil, err
+	}
+	x, y, common := align(a, b)
+	return result(number{new(big.Int).Add(x, y), common}, s), nil
+}
+
+// Sub subtracts num2 from num1
+// bcsub(string $num1, string $num2, ?int $scale = null): string
+func (c *Calculator) Sub(num1, num2 *types.Value, scale ...*types.Value) (*types.Value, error) {
+	a, b, s, err := c.binaryArguments("bcsub", num1, num2, scale)
+	if err != nil {
+		return nil, err
+	}
+	x, y, common := align(a, b)
+	return result(number{new(big.Int).Sub(x, y), common}, s), nil
+}
+
+// Mul multiplies two numbers
+// bcmul(string $num1, string $num2, ?int $scale = null): string
+func (c *Calculator) Mul(num1, num2 *types.Value, scale ...*types.Value) (*types.Value, error) {
+	a, b, s, err := c.binaryArguments("bcmul", num1, num2, scale)
+	if err != nil {
+		return nil, err
+	}
+	return result(number{new(big.Int).Mul(a.digits, b.digits), a.scale + b.scale}, s), nil
+}
+
+// Div divides num1 by num2, truncating the quotient to the scale
+// bcdiv(string $num1, string $num2, ?int $scale = null): string
+func (c *Calculator) Div(num1, num2 *types.Value, scale ...*types.Value) (*types.Value, error) {
+	a, b, s, err := c.binaryArguments("bcdiv", num1, num2, scale)
+	if err != nil {
+		return nil, err
+	}
+	if b.digits.Sign() == 0 {
+		return nil, &Error{Class: "DivisionByZeroError", Message: "Division by zero"}
+	}
+	return result(quotient(a, b, s), s), nil
+}
+
+// Mod returns the remainder of num1 divided by num2, which has the sign
+// of num1
+// bcmod(string $num1, string $num2, ?int $scale = null): string
+func (c *Calculator) Mod(num1, num2 *types.Value, scale ...*types.Value) (*types.Value, error) {
+	a, b, s, err := c.binaryArguments("bcmod", num1, num2, scale)
+	if err != nil {
+		return nil, err
+	}
+	if b.digits.Sign() == 0 {
+		return nil, &Error{Class: "DivisionByZeroError", Message: "Modulo by zero"}
+	}
+	x, y, common := align(a, b)
+	return result(number{new(big.Int).Rem(x, y), common}, s), nil
+}
+
+// Pow raises num to an integer power; negative exponents divide 1 by the
+// power, truncated to the scale
+// bcpow(string $num, string $exponent, ?int $scale = null): string
+func (c *Calculator) Pow(num, exponent *types.Value, scale ...*types.Value) (*types.Value, error) {
+	base, err := parse("bcpow", 1, "num", num)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := parse("bcpow", 2, "exponent", exponent)
+	if err != nil {
+		return nil, err
+	}
+	s, err := c.scaleArgument("bcpow", 3, scale)
+	if err != nil {
+		return nil, err
+	}
+	if exp.truncate(0).cmp(exp) != 0 {
+		return nil, valueError("bcpow(): Argument #2 ($exponent) cannot have a fractional part")
+	}
+	e := exp.truncate(0).digits
+	if !e.IsInt64() || e.Int64() > math.MaxInt32 || e.Int64() < -math.MaxInt32 {
+		return nil, valueError("bcpow(): Argument #2 ($exponent) is too large")
+	}
+
+	n := e.Int64()
+	power := number{new(big.Int).Exp(base.digits, big.NewInt(abs(n)), nil), base.scale * abs(n)}
+	if n >= 0 {
+		return result(power, s), nil
+	}
+	if power.digits.Sign() == 0 {
+		return nil, &Error{Class: "DivisionByZeroError", Message: "Negative power of zero"}
+	}
+	return result(quotient(number{big.NewInt(1), 0}, power, s), s), nil
+}
+
+// Sqrt returns the square root of a number, truncated to the scale
+// bcsqrt(string $num, ?int $scale = null): string
+func (c *Calculator) Sqrt(num *types.Value, scale ...*types.Value) (*types.Value, error) {
+	a, err := parse("bcsqrt", 1, "num", num)
+	if err != nil {
+		return nil, err
+	}
+	s, err := c.scaleArgument("bcsqrt", 2, scale)
+	if err != nil {
+		return nil, err
+	}
+	if a.digits.Sign() < 0 {
+		return nil, valueError("bcsqrt(): Argument #1 ($num) must be greater than or equal to 0")
+	}
+	// sqrt(digits / 10^scale) * 10^k = sqrt(digits * 10^(2k - scale))
+	k := s
+	if a.scale > k {
+		k = a.scale
+	}
+	radicand := new(big.Int).Mul(a.digits, pow10(2*k-a.scale))
+	return result(number{new(big.Int).Sqrt(radicand), k}, s), nil
+}
+
+// ============================================================================
+// Comparison and Scale
+// ============================================================================
+
+// Comp compares two numbers truncated to the scale, returning -1, 0 or 1
+// bccomp(string $num1, string $num2, ?int $scale = null): int
+func (c *Calculator) Comp(num1, num2 *types.Value, scale ...*types.Value) (*types.Value, error) {
+	a, b, s, err := c.binaryArguments("bccomp", num1, num2, scale)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewInt(int64(a.truncate(s).cmp(b.truncate(s)))), nil
+}
+
+// SetScale sets the default scale and returns the previous one; without
+// an argument (or with null) it only returns the current scale
+// bcscale(?int $scale = null): int
+func (c *Calculator) SetScale(scale ...*types.Value) (*types.Value, error) {
+	old := c.Scale
+	s, err := c.scaleArgument("bcscale", 1, scale)
+	if err != nil {
+		return nil, err
+	}
+	c.Scale = s
+	return types.NewInt(old), nil
+}
+
+// ============================================================================
+// Numbers
+// ============================================================================
+
+// number is an exact decimal number: digits / 10^scale
+type number struct {
+	digits *big.Int
+	scale  int64
+}
+
+// parse parses a BCMath number: an optional sign, digits and an optional
+// fractional part, without exponent or whitespace. The empty string is 0.
+func parse(function string, position int, name string, v *types.Value) (number, error) {
+	s := v.ToString()
+	negative := false
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+	integer, fraction, _ := strings.Cut(s, ".")
+	if !allDigits(integer) || !allDigits(fraction) {
+		return number{}, valueError("%s(): Argument #%d ($%s) is not well-formed", function, position, name)
+	}
+
+	digits, _ := new(big.Int).SetString("0"+integer+fraction, 10)
+	if negative {
+		digits.Neg(digits)
+	}
+	return number{digits, int64(len(fraction))}, nil
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// binaryArguments parses the two numbers and the scale of a function
+// taking two numbers
+func (c *Calculator) binaryArguments(function string, num1, num2 *types.Value, scale []*types.Value) (number, number, int64, error) {
+	a, err := parse(function, 1, "num1", num1)
+	if err != nil {
+		return number{}, number{}, 0, err
+	}
+	b, err := parse(function, 2, "num2", num2)
+	if err != nil {
+		return number{}, number{}, 0, err
+	}
+	s, err := c.scaleArgument(function, 3, scale)
+	return a, b, s, err
+}
+
+// scaleArgument returns the scale argument of a function, or the default
+// scale if it is missing or null
+func (c *Calculator) scaleArgument(function string, position int, scale []*types.Value) (int64, error) {
+	if len(scale) == 0 || scale[0].IsNull() {
+		return c.Scale, nil
+	}
+	s := scale[0].ToInt()
+	if s < 0 || s > math.MaxInt32 {
+		return 0, valueError("%s(): Argument #%d ($scale) must be between 0 and 2147483647", function, position)
+	}
+	return s, nil
+}
+
+// align returns the digits of two numbers at their common scale
+func align(a, b number) (*big.Int, *big.Int, int64) {
+	if a.scale < b.scale {
+		return a.rescale(b.scale), b.digits, b.scale
+	}
+	return a.digits, b.rescale(a.scale), a.scale
+}
+
+// rescale returns the digits of a number at a larger scale
+func (n number) rescale(scale int64) *big.Int {
+	return new(big.Int).Mul(n.digits, pow10(scale-n.scale))
+}
+
+// truncate returns a number with at most scale decimals, truncating it
+// toward zero
+func (n number) truncate(scale int64) number {
+	if scale >= n.scale {
+		return n
+	}
+	return number{new(big.Int).Quo(n.digits, pow10(n.scale-scale)), scale}
+}
+
+func (n number) cmp(other number) int {
+	x, y, _ := align(n, other)
+	return x.Cmp(y)
+}
+
+// quotient returns a / b truncated to scale decimals:
+// a.digits * 10^(b.scale + scale) / (b.digits * 10^a.scale)
+func quotient(a, b number, scale int64) number {
+	dividend := new(big.Int).Mul(a.digits, pow10(b.scale+scale))
+	divisor := new(big.Int).Mul(b.digits, pow10(a.scale))
+	return number{dividend.Quo(dividend, divisor), scale}
+}
+
+// result formats a number with exactly scale decimals, truncating it
+func result(n number, scale int64) *types.Value {
+	return types.NewString(format(n.truncate(scale), scale))
+}
+
+// format formats a number truncated to at most scale decimals with
+// exactly scale decimals; zero has no sign
+func format(n number, scale int64) string {
+	digits := new(big.Int).Abs(n.digits).String() + strings.Repeat("0", int(scale-n.scale))
+	if pad := int(scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	s := digits
+	if scale > 0 {
+		s = digits[:len(digits)-int(scale)] + "." + digits[len(digits)-int(scale):]
+	}
+	if n.digits.Sign() < 0 {
+		return "-" + s
+	}
+	return s
+}
+
+func pow10(n int64) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(n), nil)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,106 @@
+package bcmath
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func str(s string) *types.Value {
+	return types.NewString(s)
+}
+
+func TestArithmetic(t *testing.T) {
+	c := &Calculator{}
+	tests := []struct {
+		name     string
+		call     func() (*types.Value, error)
+		expected string
+	}{
+		{"add", func() (*types.Value, error) { return c.Add(str("1.234"), str("5"), types.NewInt(4)) }, "6.2340"},
+		{"add default scale", func() (*types.Value, error) { return c.Add(str("1.9"), str("0.2")) }, "2"},
+		{"add beyond int64", func() (*types.Value, error) { return c.Add(str("9223372036854775807"), str("1")) }, "9223372036854775808"},
+		{"sub", func() (*types.Value, error) { return c.Sub(str("1"), str("2.5"), types.NewInt(1)) }, "-1.5"},
+		{"sub to negative zero", func() (*types.Value, error) { return c.Sub(str("0.001"), str("0.002"), types.NewInt(2)) }, "0.00"},
+		{"mul", func() (*types.Value, error) { return c.Mul(str("0.1"), str("0.2"), types.NewInt(3)) }, "0.020"},
+		{"mul truncates", func() (*types.Value, error) { return c.Mul(str("-2.555"), str("1"), types.NewInt(2)) }, "-2.55"},
+		{"div", func() (*types.Value, error) { return c.Div(str("1"), str("3"), types.NewInt(5)) }, "0.33333"},
+		{"div exact", func() (*types.Value, error) { return c.Div(str("105"), str("6.55957"), types.NewInt(3)) }, "16.007"},
+		{"mod", func() (*types.Value, error) { return c.Mod(str("-7"), str("3")) }, "-1"},
+		{"mod with scale", func() (*types.Value, error) { return c.Mod(str("5.7"), str("1.3"), types.NewInt(1)) }, "0.5"},
+		{"pow", func() (*types.Value, error) { return c.Pow(str("4.2"), str("3"), types.NewInt(2)) }, "74.08"},
+		{"pow large", func() (*types.Value, error) { return c.Pow(str("2"), str("64")) }, "18446744073709551616"},
+		{"pow negative", func() (*types.Value, error) { return c.Pow(str("2"), str("-2"), types.NewInt(4)) }, "0.2500"},
+		{"sqrt", func() (*types.Value, error) { return c.Sqrt(str("2"), types.NewInt(3)) }, "1.414"},
+		{"sqrt of a fraction", func() (*types.Value, error) { return c.Sqrt(str("0.0144")) }, "0"},
+		{"empty string", func() (*types.Value, error) { return c.Add(str(""), str("+.5"), types.NewInt(1)) }, "0.5"},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.call()
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if got.ToString() != tt.expected {
+			t.Errorf("%s = %q, want %q", tt.name, got.ToString(), tt.expected)
+		}
+	}
+}
+
+func TestComp(t *testing.T) {
+	c := &Calculator{}
+	tests := []struct {
+		num1, num2 string
+		scale      int64
+		expected   int64
+	}{
+		{"1", "2", 0, -1},
+		{"1.001", "1", 2, 0},
+		{"1.001", "1", 3, 1},
+		{"-0.5", "0", 1, -1},
+	}
+	for _, tt := range tests {
+		got, err := c.Comp(str(tt.num1), str(tt.num2), types.NewInt(tt.scale))
+		if err != nil || got.ToInt() != tt.expected {
+			t.Errorf("bccomp(%q, %q, %d) = %v (%v), want %d", tt.num1, tt.num2, tt.scale, got, err, tt.expected)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	c := &Calculator{}
+	if old, _ := c.SetScale(types.NewInt(3)); old.ToInt() != 0 {
+		t.Errorf("bcscale(3) = %v, want 0", old)
+	}
+	if got, _ := c.Div(str("10"), str("4")); got.ToString() != "2.500" {
+		t.Errorf("bcdiv(\"10\", \"4\") = %v with scale 3", got)
+	}
+	if current, _ := c.SetScale(); current.ToInt() != 3 || c.Scale != 3 {
+		t.Errorf("bcscale() = %v", current)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c := &Calculator{}
+	tests := []struct {
+		call    func() (*types.Value, error)
+		class   string
+		message string
+	}{
+		{func() (*types.Value, error) { return c.Add(str("1e3"), str("1")) }, "ValueError", "bcadd(): Argument #1 ($num1) is not well-formed"},
+		{func() (*types.Value, error) { return c.Mul(str("1"), str(" 1")) }, "ValueError", "bcmul(): Argument #2 ($num2) is not well-formed"},
+		{func() (*types.Value, error) { return c.Div(str("1"), str("0.00")) }, "DivisionByZeroError", "Division by zero"},
+		{func() (*types.Value, error) { return c.Mod(str("1"), str("0")) }, "DivisionByZeroError", "Modulo by zero"},
+		{func() (*types.Value, error) { return c.Pow(str("2"), str("1.5")) }, "ValueError", "bcpow(): Argument #2 ($exponent) cannot have a fractional part"},
+		{func() (*types.Value, error) { return c.Sqrt(str("-4")) }, "ValueError", "bcsqrt(): Argument #1 ($num) must be greater than or equal to 0"},
+		{func() (*types.Value, error) { return c.Sub(str("1"), str("1"), types.NewInt(-1)) }, "ValueError", "bcsub(): Argument #3 ($scale) must be between 0 and 2147483647"},
+	}
+	for _, tt := range tests {
+		_, err := tt.call()
+		e, ok := err.(*Error)
+		if !ok || e.Class != tt.class || e.Message != tt.message {
+			t.Errorf("Expected %s %q, got %v", tt.class, tt.message, err)
+		}
+	}
+}
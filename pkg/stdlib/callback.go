@@ -0,0 +1,18 @@
+package stdlib
+
+import "github.com/krizos/php-go/pkg/types"
+
+// Caller invokes PHP callables on behalf of standard library functions.
+// The VM implements it, so functions such as array_map or usort can call
+// user functions and closures without depending on the VM package.
+type Caller interface {
+	CallCallable(callable *types.Value, args []*types.Value) (*types.Value, error)
+}
+
+// CallerFunc adapts an ordinary Go function to the Caller interface
+type CallerFunc func(callable *types.Value, args []*types.Value) (*types.Value, error)
+
+// CallCallable calls f(callable, args)
+func (f CallerFunc) CallCallable(callable *types.Value, args []*types.Value) (*types.Value, error) {
+	return f(callable, args)
+}
@@ -0,0 +1,304 @@
+package classobj
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/stdlib/reflection"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Class and Object Functions
+// PHP's functions inspecting the declared classes and their instances
+// ============================================================================
+
+// Classes gives the class functions access to the classes declared in the
+// VM and to the class scope of the code calling them, which decides the
+// members they can see. The VM implements it for the calling frame.
+type Classes interface {
+	// LookupClass returns a declared class, interface, trait or enum by
+	// its case-insensitive name; with autoload set, the autoloaders run
+	// first for a name that is not declared
+	LookupClass(name string, autoload bool) (*types.ClassEntry, bool, error)
+	// Scope returns the class the calling code runs in, nil outside
+	// classes
+	Scope() *types.ClassEntry
+}
+
+// Error is an error thrown by a class function, such as the TypeError for
+// an argument that is neither an object nor a class name. Class names the
+// PHP exception.
+type Error struct {
+	Class   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func typeError(format string, args ...interface{}) error {
+	return &Error{Class: "TypeError", Message: fmt.Sprintf(format, args...)}
+}
+
+// ============================================================================
+// Existence Checks
+// ============================================================================
+
+// ClassExists checks whether a class (or an enum) is declared
+// class_exists(string $class, bool $autoload = true): bool
+func ClassExists(classes Classes, name *types.Value, autoload ...*types.Value) (*types.Value, error) {
+	return exists(classes, name, autoload, func(class *types.ClassEntry) bool {
+		return !class.IsInterface && !class.IsTrait
+	})
+}
+
+// InterfaceExists checks whether an interface is declared
+// interface_exists(string $interface, bool $autoload = true): bool
+func InterfaceExists(classes Classes, name *types.Value, autoload ...*types.Value) (*types.Value, error) {
+	return exists(classes, name, autoload, func(class *types.ClassEntry) bool {
+		return class.IsInterface
+	})
+}
+
+// TraitExists checks whether a trait is declared
+// trait_exists(string $trait, bool $autoload = true): bool
+func TraitExists(classes Classes, name *types.Value, autoload ...*types.Value) (*types.Value, error) {
+	return exists(classes, name, autoload, func(class *types.ClassEntry) bool {
+		return class.IsTrait
+	})
+}
+
+// EnumExists checks whether an enum is declared
+// enum_exists(string $enum, bool $autoload = true): bool
+func EnumExists(classes Classes, name *types.Value, autoload ...*types.Value) (*types.Value, error) {
+	return exists(classes, name, autoload, func(class *types.ClassEntry) bool {
+		return class.IsEnum
+	})
+}
+
+// exists looks up a class, autoloading it unless the autoload argument is
+// false, and checks its kind
+func exists(classes Classes, name *types.Value, autoload []*types.Value, kind func(*types.ClassEntry) bool) (*types.Value, error) {
+	load := len(autoload) == 0 || autoload[0].ToBool()
+	class, ok, err := classes.LookupClass(strings.TrimPrefix(name.ToString(), "\\"), load)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewBool(ok && kind(class)), nil
+}
+
+// MethodExists checks whether an object or class has a method, whatever
+// its visibility
+// method_exists(object|string $object_or_class, string $method): bool
+func MethodExists(classes Classes, objectOrClass, method *types.Value) (*types.Value, error) {
+	class, ok, err := classArgument(classes, "method_exists", objectOrClass)
+	if err != nil || !ok {
+		return types.NewBool(false), err
+	}
+	_, found := reflection.FindMethod(class, method.ToString())
+	return types.NewBool(found), nil
+}
+
+// PropertyExists checks whether an object or class has a property,
+// whatever its visibility: a declared property, static or not, or a
+// dynamic property of the object
+// property_exists(object|string $object_or_class, string $property): bool
+func PropertyExists(classes Classes, objectOrClass, property *types.Value) (*types.Value, error) {
+	name := property.ToString()
+	if objectOrClass.IsObject() {
+		if _, ok := objectOrClass.ToObject().Properties[name]; ok {
+			return types.NewBool(true), nil
+		}
+	}
+	class, ok, err := classArgument(classes, "property_exists", objectOrClass)
+	if err != nil || !ok {
+		return types.NewBool(false), err
+	}
+	_, found := class.Properties[name]
+	return types.NewBool(found), nil
+}
+
+// classArgument returns the class of an object, or the class named by a
+// string, which is autoloaded
+func classArgument(classes Classes, function string, objectOrClass *types.Value) (*types.ClassEntry, bool, error) {
+	switch {
+	case objectOrClass.IsObject():
+		class := objectOrClass.ToObject().ClassEntry
+		return class, class != nil, nil
+	case objectOrClass.IsString():
+		return classes.LookupClass(strings.TrimPrefix(objectOrClass.ToString(), "\\"), true)
+	}
+	return nil, false, typeError("%s(): Argument #1 ($object_or_class) must be of type object|string, %s given", function, objectOrClass.TypeName())
+}
+
+// ============================================================================
+// Class Names
+// ============================================================================
+
+// GetClass returns the class name of an object, or without an argument
+// the class the calling code runs in
+// get_class(object $object = ?): string
+func GetClass(classes Classes, object ...*types.Value) (*types.Value, error) {
+	if len(object) == 0 {
+		if scope := classes.Scope(); scope != nil {
+			return types.NewString(scope.Name), nil
+		}
+		return nil, &Error{Class: "Error", Message: "get_class() without arguments must be called from within a class"}
+	}
+	if !object[0].IsObject() {
+		return nil, typeError("get_class(): Argument #1 ($object) must be of type object, %s given", object[0].TypeName())
+	}
+	return types.NewString(object[0].ToObject().ClassName), nil
+}
+
+// GetParentClass returns the name of the parent class of an object or
+// class, or without an argument of the class the calling code runs in;
+// false if there is none
+// get_parent_class(object|string $object_or_class = ?): string|false
+func GetParentClass(classes Classes, objectOrClass ...*types.Value) (*types.Value, error) {
+	class := classes.Scope()
+	if len(objectOrClass) > 0 {
+		var err error
+		if class, err = validClass(classes, "get_parent_class", objectOrClass[0]); err != nil {
+			return nil, err
+		}
+	}
+	if class == nil || class.ParentClass == nil {
+		return types.NewBool(false), nil
+	}
+	return types.NewString(class.ParentClass.Name), nil
+}
+
+// validClass returns the class of an object or the class named by a
+// string, which must exist
+func validClass(classes Classes, function string, objectOrClass *types.Value) (*types.ClassEntry, error) {
+	class, ok, err := classArgument(classes, function, objectOrClass)
+	if err != nil {
+		if _, typeErr := err.(*Error); typeErr {
+			err = typeError("%s(): Argument #1 ($object_or_class) must be an object or a valid class name, %s given", function, objectOrClass.TypeName())
+		}
+		return nil, err
+	}
+	if !ok {
+		return nil, typeError("%s(): Argument #1 ($object_or_class) must be an object or a valid class name, %s given", function, objectOrClass.TypeName())
+	}
+	return class, nil
+}
+
+// IsA checks whether an object, or with allowString a class name, is of a
+// class or has it as one of its parents or interfaces
+// is_a(mixed $object_or_class, string $class, bool $allow_string = false): bool
+func IsA(classes Classes, objectOrClass, className *types.Value, allowString ...*types.Value) (*types.Value, error) {
+	class, ok, err := instanceClass(classes, objectOrClass, len(allowString) > 0 && allowString[0].ToBool())
+	if err != nil || !ok {
+		return types.NewBool(false), err
+	}
+	return types.NewBool(instanceOf(class, className.ToString())), nil
+}
+
+// IsSubclassOf checks whether an object or class name has a class as one
+// of its parents or interfaces
+// is_subclass_of(mixed $object_or_class, string $class, bool $allow_string = true): bool
+func IsSubclassOf(classes Classes, objectOrClass, className *types.Value, allowString ...*types.Value) (*types.Value, error) {
+	class, ok, err := instanceClass(classes, objectOrClass, len(allowString) == 0 || allowString[0].ToBool())
+	if err != nil || !ok {
+		return types.NewBool(false), err
+	}
+	name := strings.TrimPrefix(className.ToString(), "\\")
+	return types.NewBool(!strings.EqualFold(class.Name, name) && instanceOf(class, name)), nil
+}
+
+// instanceClass returns the class of an object, or the class named by a
+// string if allowString is set
+func instanceClass(classes Classes, objectOrClass *types.Value, allowString bool) (*types.ClassEntry, bool, error) {
+	switch {
+	case objectOrClass.IsObject():
+		class := objectOrClass.ToObject().ClassEntry
+		return class, class != nil, nil
+	case objectOrClass.IsString() && allowString:
+		return classes.LookupClass(strings.TrimPrefix(objectOrClass.ToString(), "\\"), true)
+	}
+	return nil, false, nil
+}
+
+// instanceOf reports whether a class is the named class or has it as one
+// of its parents or interfaces
+func instanceOf(class *types.ClassEntry, name string) bool {
+	name = strings.TrimPrefix(name, "\\")
+	return extends(class, name) || (class != nil && class.ImplementsInterface(name))
+}
+
+// ============================================================================
+// Members
+// ============================================================================
+
+// GetObjectVars returns the non-static properties of an object the
+// calling scope can access, with their values
+// get_object_vars(object $object): array
+func GetObjectVars(classes Classes, object *types.Value) (*types.Value, error) {
+	if !object.IsObject() {
+		return nil, typeError("get_object_vars(): Argument #1 ($object) must be of type object, %s given", object.TypeName())
+	}
+	scope := classes.Scope()
+	vars := types.NewEmptyArray()
+	for _, prop := range object.ToObject().DebugProperties() {
+		if visible(classes, prop.Visibility, prop.Class, scope) {
+			vars.Set(prop.Key, prop.Value.Deref())
+		}
+	}
+	return types.NewArray(vars), nil
+}
+
+// GetClassMethods returns the names of the methods of an object or class
+// the calling scope can access: the class's own methods first, then the
+// ones it inherits
+// get_class_methods(object|string $object_or_class): array
+func GetClassMethods(classes Classes, objectOrClass *types.Value) (*types.Value, error) {
+	class, err := validClass(classes, "get_class_methods", objectOrClass)
+	if err != nil {
+		return nil, err
+	}
+	scope := classes.Scope()
+	names := types.NewEmptyArray()
+	for _, method := range reflection.Methods(class) {
+		declaring := method.DeclaringClass
+		if declaring == "" {
+			declaring = class.Name
+		}
+		if visible(classes, method.Visibility, declaring, scope) {
+			names.Append(types.NewString(method.Name))
+		}
+	}
+	return types.NewArray(names), nil
+}
+
+// visible reports whether a member of a class is accessible from a class
+// scope (nil outside classes): public members from anywhere, protected
+// ones from the classes related to the declaring class by inheritance and
+// private ones from the declaring class only
+func visible(classes Classes, visibility types.PropertyVisibility, declaring string, scope *types.ClassEntry) bool {
+	switch visibility {
+	case types.VisibilityPublic:
+		return true
+	case types.VisibilityPrivate:
+		return scope != nil && strings.EqualFold(scope.Name, declaring)
+	}
+	if scope == nil {
+		return false
+	}
+	class, ok, _ := classes.LookupClass(declaring, false)
+	return extends(scope, declaring) || (ok && extends(class, scope.Name))
+}
+
+// extends reports whether a class is the named class or one of its
+// subclasses; interfaces do not relate classes for member access
+func extends(class *types.ClassEntry, name string) bool {
+	for current := class; current != nil; current = current.ParentClass {
+		if strings.EqualFold(current.Name, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,283 @@
+package classobj
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// testClasses is a class registry with an optional autoloader and scope
+type testClasses struct {
+	classes  map[string]*types.ClassEntry
+	autoload map[string]*types.ClassEntry
+	loaded   []string
+	scope    *types.ClassEntry
+}
+
+func (c *testClasses) LookupClass(name string, autoload bool) (*types.ClassEntry, bool, error) {
+	key := strings.ToLower(name)
+	if class, ok := c.classes[key]; ok {
+		return class, true, nil
+	}
+	if class, ok := c.autoload[key]; ok && autoload {
+		c.loaded = append(c.loaded, name)
+		c.classes[key] = class
+		return class, true, nil
+	}
+	return nil, false, nil
+}
+
+func (c *testClasses) Scope() *types.ClassEntry {
+	return c.scope
+}
+
+// newTestClasses declares the interface Shape, the class Base implementing
+// it and its subclass Square, and an autoloadable trait Named
+func newTestClasses() *testClasses {
+	shape := types.NewClassEntry("Shape")
+	shape.IsInterface = true
+
+	base := types.NewClassEntry("Base")
+	base.Interfaces = []*types.InterfaceEntry{{Name: "Shape"}}
+	addProperty(base, "name", types.VisibilityPublic, "Base")
+	addProperty(base, "size", types.VisibilityProtected, "Base")
+	addProperty(base, "secret", types.VisibilityPrivate, "Base")
+	addMethod(base, "area", types.VisibilityPublic, "Base")
+	addMethod(base, "scale", types.VisibilityProtected, "Base")
+	addMethod(base, "hidden", types.VisibilityPrivate, "Base")
+
+	square := types.NewClassEntry("Square")
+	square.ParentClass = base
+	for name, prop := range base.Properties {
+		square.Properties[name] = prop
+	}
+	addMethod(square, "side", types.VisibilityPublic, "Square")
+	square.Methods["area"] = base.Methods["area"]
+
+	named := types.NewClassEntry("Named")
+	named.IsTrait = true
+
+	return &testClasses{
+		classes: map[string]*types.ClassEntry{
+			"shape": shape, "base": base, "square": square,
+		},
+		autoload: map[string]*types.ClassEntry{"named": named},
+	}
+}
+
+func addProperty(class *types.ClassEntry, name string, visibility types.PropertyVisibility, declaring string) {
+	class.Properties[name] = &types.PropertyDef{Name: name, Visibility: visibility, DeclaringClass: declaring, Default: types.NewString(name)}
+}
+
+func addMethod(class *types.ClassEntry, name string, visibility types.PropertyVisibility, declaring string) {
+	class.Methods[name] = &types.MethodDef{Name: name, Visibility: visibility, DeclaringClass: declaring}
+}
+
+func expectError(t *testing.T, err error, class, message string) {
+	t.Helper()
+	e, ok := err.(*Error)
+	if !ok || e.Class != class || e.Message != message {
+		t.Errorf("Expected %s %q, got %v", class, message, err)
+	}
+}
+
+// keys returns the keys of an array value, or its values with values set
+func keys(t *testing.T, result *types.Value, err error, values bool) string {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	result.ToArray().Each(func(key, value *types.Value) bool {
+		if values {
+			key = value
+		}
+		names = append(names, key.ToString())
+		return true
+	})
+	return strings.Join(names, ",")
+}
+
+// ============================================================================
+// Existence Checks
+// ============================================================================
+
+func TestExists(t *testing.T) {
+	classes := newTestClasses()
+	tests := []struct {
+		fn       func(Classes, *types.Value, ...*types.Value) (*types.Value, error)
+		name     string
+		expected bool
+	}{
+		{ClassExists, "Square", true},
+		{ClassExists, "\\square", true},
+		{ClassExists, "Shape", false},
+		{ClassExists, "Missing", false},
+		{InterfaceExists, "shape", true},
+		{InterfaceExists, "Base", false},
+		{EnumExists, "Base", false},
+	}
+	for _, tt := range tests {
+		result, err := tt.fn(classes, types.NewString(tt.name))
+		if err != nil || result.ToBool() != tt.expected {
+			t.Errorf("%s: expected %v, got %v (%v)", tt.name, tt.expected, result, err)
+		}
+	}
+
+	// The autoloaders run unless autoload is false
+	if result, _ := TraitExists(classes, types.NewString("Named"), types.NewBool(false)); result.ToBool() {
+		t.Error("Expected Named not to be loaded without autoloading")
+	}
+	if result, _ := TraitExists(classes, types.NewString("Named")); !result.ToBool() || len(classes.loaded) != 1 {
+		t.Errorf("Expected Named to be autoloaded, got %v (loaded %v)", result, classes.loaded)
+	}
+}
+
+func TestMethodAndPropertyExists(t *testing.T) {
+	classes := newTestClasses()
+	obj := types.NewObjectFromClass(classes.classes["square"])
+	obj.Properties["dynamic"] = &types.Property{Value: types.NewInt(1), Visibility: types.VisibilityPublic}
+
+	tests := []struct {
+		fn       func(Classes, *types.Value, *types.Value) (*types.Value, error)
+		target   *types.Value
+		member   string
+		expected bool
+	}{
+		{MethodExists, types.NewString("Square"), "AREA", true},
+		{MethodExists, types.NewString("Square"), "hidden", true},
+		{MethodExists, types.NewObject(obj), "side", true},
+		{MethodExists, types.NewString("Base"), "side", false},
+		{MethodExists, types.NewString("Missing"), "side", false},
+		{PropertyExists, types.NewString("Square"), "secret", true},
+		{PropertyExists, types.NewString("Square"), "Secret", false},
+		{PropertyExists, types.NewObject(obj), "dynamic", true},
+		{PropertyExists, types.NewString("Square"), "dynamic", false},
+	}
+	for _, tt := range tests {
+		result, err := tt.fn(classes, tt.target, types.NewString(tt.member))
+		if err != nil || result.ToBool() != tt.expected {
+			t.Errorf("%v %s: expected %v, got %v (%v)", tt.target, tt.member, tt.expected, result, err)
+		}
+	}
+
+	_, err := MethodExists(classes, types.NewInt(1), types.NewString("area"))
+	expectError(t, err, "TypeError", "method_exists(): Argument #1 ($object_or_class) must be of type object|string, int given")
+}
+
+// ============================================================================
+// Class Names
+// ============================================================================
+
+func TestGetClass(t *testing.T) {
+	classes := newTestClasses()
+	obj := types.NewObject(types.NewObjectFromClass(classes.classes["square"]))
+
+	if result, _ := GetClass(classes, obj); result.ToString() != "Square" {
+		t.Errorf("get_class() = %v", result)
+	}
+	_, err := GetClass(classes)
+	expectError(t, err, "Error", "get_class() without arguments must be called from within a class")
+	_, err = GetClass(classes, types.NewString("Square"))
+	expectError(t, err, "TypeError", "get_class(): Argument #1 ($object) must be of type object, string given")
+
+	classes.scope = classes.classes["square"]
+	if result, _ := GetClass(classes); result.ToString() != "Square" {
+		t.Errorf("get_class() in Square = %v", result)
+	}
+	if result, _ := GetParentClass(classes); result.ToString() != "Base" {
+		t.Errorf("get_parent_class() in Square = %v", result)
+	}
+	if result, _ := GetParentClass(classes, types.NewString("Base")); result.IsBool() && result.ToBool() {
+		t.Errorf("Expected false for a class without parent, got %v", result)
+	}
+	_, err = GetParentClass(classes, types.NewString("Missing"))
+	expectError(t, err, "TypeError", "get_parent_class(): Argument #1 ($object_or_class) must be an object or a valid class name, string given")
+}
+
+func TestIsAAndIsSubclassOf(t *testing.T) {
+	classes := newTestClasses()
+	obj := types.NewObject(types.NewObjectFromClass(classes.classes["square"]))
+	square := types.NewString("Square")
+
+	tests := []struct {
+		name     string
+		fn       func(Classes, *types.Value, *types.Value, ...*types.Value) (*types.Value, error)
+		target   *types.Value
+		class    string
+		allow    []*types.Value
+		expected bool
+	}{
+		{"is_a object", IsA, obj, "Square", nil, true},
+		{"is_a parent", IsA, obj, "base", nil, true},
+		{"is_a interface", IsA, obj, "Shape", nil, true},
+		{"is_a unrelated", IsA, obj, "Named", nil, false},
+		{"is_a string", IsA, square, "Base", nil, false},
+		{"is_a allowed string", IsA, square, "Base", []*types.Value{types.NewBool(true)}, true},
+		{"is_subclass_of itself", IsSubclassOf, obj, "Square", nil, false},
+		{"is_subclass_of parent", IsSubclassOf, obj, "Base", nil, true},
+		{"is_subclass_of string", IsSubclassOf, square, "Shape", nil, true},
+		{"is_subclass_of disallowed string", IsSubclassOf, square, "Shape", []*types.Value{types.NewBool(false)}, false},
+		{"is_subclass_of missing", IsSubclassOf, types.NewString("Missing"), "Base", nil, false},
+	}
+	for _, tt := range tests {
+		result, err := tt.fn(classes, tt.target, types.NewString(tt.class), tt.allow...)
+		if err != nil || result.ToBool() != tt.expected {
+			t.Errorf("%s: expected %v, got %v (%v)", tt.name, tt.expected, result, err)
+		}
+	}
+}
+
+// ============================================================================
+// Members
+// ============================================================================
+
+func TestGetObjectVars(t *testing.T) {
+	classes := newTestClasses()
+	obj := types.NewObjectFromClass(classes.classes["square"])
+	obj.AddProperty("dynamic", &types.Property{Value: types.NewInt(1), Visibility: types.VisibilityPublic})
+
+	// Declared properties come first, dynamic ones after them
+	tests := []struct {
+		scope    string
+		expected string
+	}{
+		{"", "name,dynamic"},
+		{"square", "name,size,dynamic"},
+		{"base", "name,secret,size,dynamic"},
+		{"shape", "name,dynamic"},
+	}
+	for _, tt := range tests {
+		classes.scope = classes.classes[tt.scope]
+		result, err := GetObjectVars(classes, types.NewObject(obj))
+		if got := keys(t, result, err, false); got != tt.expected {
+			t.Errorf("get_object_vars() from %q = %s, want %s", tt.scope, got, tt.expected)
+		}
+	}
+
+	result, _ := GetObjectVars(classes, types.NewObject(obj))
+	if name, _ := result.ToArray().Get(types.NewString("name")); name.ToString() != "name" {
+		t.Errorf("Expected the property value, got %v", name)
+	}
+
+	_, err := GetObjectVars(classes, types.NewNull())
+	expectError(t, err, "TypeError", "get_object_vars(): Argument #1 ($object) must be of type object, null given")
+}
+
+func TestGetClassMethods(t *testing.T) {
+	classes := newTestClasses()
+
+	result, err := GetClassMethods(classes, types.NewString("Square"))
+	if got := keys(t, result, err, true); got != "area,side" {
+		t.Errorf("get_class_methods() = %s", got)
+	}
+	classes.scope = classes.classes["base"]
+	result, err = GetClassMethods(classes, types.NewString("Square"))
+	if got := keys(t, result, err, true); !strings.Contains(got, "hidden") || !strings.Contains(got, "scale") {
+		t.Errorf("get_class_methods() from Base = %s", got)
+	}
+
+	_, err = GetClassMethods(classes, types.NewString("Missing"))
+	expectError(t, err, "TypeError", "get_class_methods(): Argument #1 ($object_or_class) must be an object or a valid class name, string given")
+}
@@ -1,7 +1,7 @@
 package ctype
 
 import (
-	"unicode"
+	"strconv"
 
 	"github.com/krizos/php-go/pkg/types"
 )
@@ -11,210 +11,122 @@ import (
 // PHP's ctype functions check character types in strings
 // ============================================================================
 
+// The functions classify bytes in the "C" locale, like PHP's default
+// locale: bytes above 0x7f belong to no class, so "café" is not alphabetic.
+//
+// An int argument between -128 and 255 is taken as the code of a single
+// character (negative values are shifted by 256); other ints are checked
+// as the string of their digits. Arguments of other types and the empty
+// string give false.
+
 // CtypeAlnum checks for alphanumeric character(s)
 // ctype_alnum(mixed $text): bool
 func CtypeAlnum(text *types.Value) *types.Value {
-	str := text.ToString()
-	if str == "" {
-		return types.NewBool(false)
-	}
-
-	for _, r := range str {
-		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
-			return types.NewBool(false)
-		}
-	}
-
-	return types.NewBool(true)
+	return check(text, func(c byte) bool { return isAlpha(c) || isDigit(c) })
 }
 
 // CtypeAlpha checks for alphabetic character(s)
 // ctype_alpha(mixed $text): bool
 func CtypeAlpha(text *types.Value) *types.Value {
-	str := text.ToString()
-	if str == "" {
-		return types.NewBool(false)
-	}
-
-	for _, r := range str {
-		if !unicode.IsLetter(r) {
-			return types.NewBool(false)
-		}
-	}
-
-	return types.NewBool(true)
+	return check(text, isAlpha)
 }
 
 // CtypeCntrl checks for control character(s)
 // ctype_cntrl(mixed $text): bool
 func CtypeCntrl(text *types.Value) *types.Value {
-	str := text.ToString()
-	if str == "" {
-		return types.NewBool(false)
-	}
-
-	for _, r := range str {
-		if !unicode.IsControl(r) {
-			return types.NewBool(false)
-		}
-	}
-
-	return types.NewBool(true)
+	return check(text, func(c byte) bool { return c < 0x20 || c == 0x7f })
 }
 
 // CtypeDigit checks for numeric character(s)
 // ctype_digit(mixed $text): bool
 func CtypeDigit(text *types.Value) *types.Value {
-	str := text.ToString()
-	if str == "" {
-		return types.NewBool(false)
-	}
-
-	for _, r := range str {
-		if !unicode.IsDigit(r) {
-			return types.NewBool(false)
-		}
-	}
-
-	return types.NewBool(true)
+	return check(text, isDigit)
 }
 
 // CtypeGraph checks for any printable character(s) except space
 // ctype_graph(mixed $text): bool
 func CtypeGraph(text *types.Value) *types.Value {
-	str := text.ToString()
-	if str == "" {
-		return types.NewBool(false)
-	}
-
-	for _, r := range str {
-		if !unicode.IsGraphic(r) || unicode.IsSpace(r) {
-			return types.NewBool(false)
-		}
-	}
-
-	return types.NewBool(true)
+	return check(text, func(c byte) bool { return c > 0x20 && c < 0x7f })
 }
 
 // CtypeLower checks for lowercase character(s)
 // ctype_lower(mixed $text): bool
 func CtypeLower(text *types.Value) *types.Value {
-	str := text.ToString()
-	if str == "" {
-		return types.NewBool(false)
-	}
-
-	hasLetter := false
-	for _, r := range str {
-		if unicode.IsLetter(r) {
-			hasLetter = true
-			if !unicode.IsLower(r) {
-				return types.NewBool(false)
-			}
-		} else {
-			// Non-letters make it false
-			return types.NewBool(false)
-		}
-	}
-
-	return types.NewBool(hasLetter)
+	return check(text, func(c byte) bool { return c >= 'a' && c <= 'z' })
 }
 
 // CtypePrint checks for printable character(s)
 // ctype_print(mixed $text): bool
 func CtypePrint(text *types.Value) *types.Value {
-	str := text.ToString()
-	if str == "" {
-		return types.NewBool(false)
-	}
-
-	for _, r := range str {
-		if !unicode.IsPrint(r) {
-			return types.NewBool(false)
-		}
-	}
-
-	return types.NewBool(true)
+	return check(text, func(c byte) bool { return c >= 0x20 && c < 0x7f })
 }
 
 // CtypePunct checks for any printable character which is not whitespace or an alphanumeric character
 // ctype_punct(mixed $text): bool
 func CtypePunct(text *types.Value) *types.Value {
-	str := text.ToString()
-	if str == "" {
-		return types.NewBool(false)
-	}
-
-	for _, r := range str {
-		if !unicode.IsPunct(r) && !unicode.IsSymbol(r) {
-			return types.NewBool(false)
-		}
-	}
-
-	return types.NewBool(true)
+	return check(text, func(c byte) bool { return c > 0x20 && c < 0x7f && !isAlpha(c) && !isDigit(c) })
 }
 
 // CtypeSpace checks for whitespace character(s)
 // ctype_space(mixed $text): bool
 func CtypeSpace(text *types.Value) *types.Value {
-	str := text.ToString()
-	if str == "" {
-		return types.NewBool(false)
-	}
-
-	for _, r := range str {
-		if !unicode.IsSpace(r) {
-			return types.NewBool(false)
-		}
-	}
-
-	return types.NewBool(true)
+	return check(text, func(c byte) bool { return c == ' ' || (c >= '\t' && c <= '\r') })
 }
 
 // CtypeUpper checks for uppercase character(s)
 // ctype_upper(mixed $text): bool
 func CtypeUpper(text *types.Value) *types.Value {
-	str := text.ToString()
-	if str == "" {
-		return types.NewBool(false)
-	}
-
-	hasLetter := false
-	for _, r := range str {
-		if unicode.IsLetter(r) {
-			hasLetter = true
-			if !unicode.IsUpper(r) {
-				return types.NewBool(false)
-			}
-		} else {
-			// Non-letters make it false
-			return types.NewBool(false)
-		}
-	}
-
-	return types.NewBool(hasLetter)
+	return check(text, func(c byte) bool { return c >= 'A' && c <= 'Z' })
 }
 
 // CtypeXdigit checks for hexadecimal digit character(s)
 // ctype_xdigit(mixed $text): bool
 func CtypeXdigit(text *types.Value) *types.Value {
-	str := text.ToString()
-	if str == "" {
+	return check(text, func(c byte) bool {
+		return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+	})
+}
+
+// check reports whether text is a non-empty string of bytes of a class,
+// or an int standing for a character of it
+func check(text *types.Value, class func(c byte) bool) *types.Value {
+	if text == nil {
+		return types.NewBool(false)
+	}
+	text = text.Deref()
+
+	var str string
+	switch {
+	case text.IsInt():
+		n := text.ToInt()
+		switch {
+		case n >= -128 && n < 0:
+			return types.NewBool(class(byte(n + 256)))
+		case n >= 0 && n <= 255:
+			return types.NewBool(class(byte(n)))
+		}
+		str = strconv.FormatInt(n, 10)
+	case text.IsString():
+		str = text.ToString()
+	default:
 		return types.NewBool(false)
 	}
 
-	for _, r := range str {
-		if !isHexDigit(r) {
+	if str == "" {
+		return types.NewBool(false)
+	}
+	for i := 0; i < len(str); i++ {
+		if !class(str[i]) {
 			return types.NewBool(false)
 		}
 	}
-
 	return types.NewBool(true)
 }
 
-// isHexDigit checks if a rune is a hexadecimal digit (0-9, a-f, A-F)
-func isHexDigit(r rune) bool {
-	return (r >= '0' && r <= '9') ||
-		(r >= 'a' && r <= 'f') ||
-		(r >= 'A' && r <= 'F')
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
 }
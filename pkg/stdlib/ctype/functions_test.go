@@ -387,33 +387,47 @@ func TestCtypeSingleCharacter(t *testing.T) {
 }
 
 // ============================================================================
-// Unicode Tests
+// Locale and Integer Tests
 // ============================================================================
 
-func TestCtypeUnicode(t *testing.T) {
-	// Test with unicode characters
+func TestCtypeNonASCII(t *testing.T) {
+	// Bytes above 0x7f belong to no class in the "C" locale
+	for _, input := range []string{"café", "hello世界", "Москва", "\xff"} {
+		value := types.NewString(input)
+		if CtypeAlpha(value).ToBool() || CtypeAlnum(value).ToBool() || CtypePrint(value).ToBool() || CtypeCntrl(value).ToBool() {
+			t.Errorf("Expected %q to fail every class", input)
+		}
+	}
+}
+
+func TestCtypeIntegers(t *testing.T) {
 	tests := []struct {
-		input string
-		alpha bool
-		alnum bool
+		fn       func(*types.Value) *types.Value
+		input    int64
+		expected bool
 	}{
-		{"café", true, true},
-		{"hello世界", true, true},
-		{"Москва", true, true},
-		{"日本語", true, true},
+		{CtypeDigit, 53, true},    // "5"
+		{CtypeDigit, 5, false},    // a control character
+		{CtypeCntrl, 5, true},     // a control character
+		{CtypeDigit, 256, true},   // "256"
+		{CtypeDigit, -1, false},   // byte 255
+		{CtypeDigit, -129, false}, // "-129"
+		{CtypeAlpha, 65, true},    // "A"
+		{CtypeAlpha, -191, false}, // "-191"
+		{CtypeUpper, 1000, false},
+		{CtypeXdigit, 1000, true},
+		{CtypeSpace, 32, true},
 	}
 
 	for _, tt := range tests {
-		input := types.NewString(tt.input)
-
-		result := CtypeAlpha(input)
-		if result.ToBool() != tt.alpha {
-			t.Errorf("CtypeAlpha(%q) = %v, want %v", tt.input, result.ToBool(), tt.alpha)
+		if got := tt.fn(types.NewInt(tt.input)).ToBool(); got != tt.expected {
+			t.Errorf("ctype of %d = %v, want %v", tt.input, got, tt.expected)
 		}
+	}
 
-		result = CtypeAlnum(input)
-		if result.ToBool() != tt.alnum {
-			t.Errorf("CtypeAlnum(%q) = %v, want %v", tt.input, result.ToBool(), tt.alnum)
+	for _, value := range []*types.Value{types.NewNull(), types.NewBool(true), types.NewFloat(5), types.NewArray(types.NewEmptyArray())} {
+		if CtypeDigit(value).ToBool() || CtypePrint(value).ToBool() {
+			t.Errorf("Expected %v to fail every class", value)
 		}
 	}
 }
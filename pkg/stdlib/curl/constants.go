@@ -0,0 +1,180 @@
+package curl
+
+import "github.com/krizos/php-go/pkg/types"
+
+// ============================================================================
+// Constants
+// ============================================================================
+
+// Options of curl_setopt(), with libcurl's values
+const (
+	CURLOPT_PORT              = 3
+	CURLOPT_URL               = 10002
+	CURLOPT_PROXY             = 10004
+	CURLOPT_USERPWD           = 10005
+	CURLOPT_TIMEOUT           = 13
+	CURLOPT_POSTFIELDS        = 10015
+	CURLOPT_REFERER           = 10016
+	CURLOPT_USERAGENT         = 10018
+	CURLOPT_COOKIE            = 10022
+	CURLOPT_HTTPHEADER        = 10023
+	CURLOPT_CUSTOMREQUEST     = 10036
+	CURLOPT_VERBOSE           = 41
+	CURLOPT_HEADER            = 42
+	CURLOPT_NOPROGRESS        = 43
+	CURLOPT_NOBODY            = 44
+	CURLOPT_FAILONERROR       = 45
+	CURLOPT_POST              = 47
+	CURLOPT_FOLLOWLOCATION    = 52
+	CURLOPT_PUT               = 54
+	CURLOPT_AUTOREFERER       = 58
+	CURLOPT_SSL_VERIFYPEER    = 64
+	CURLOPT_MAXREDIRS         = 68
+	CURLOPT_CONNECTTIMEOUT    = 78
+	CURLOPT_HTTPGET           = 80
+	CURLOPT_SSL_VERIFYHOST    = 81
+	CURLOPT_HTTP_VERSION      = 84
+	CURLOPT_ENCODING          = 10102
+	CURLOPT_ACCEPT_ENCODING   = 10102
+	CURLOPT_PRIVATE           = 10103
+	CURLOPT_HTTPAUTH          = 107
+	CURLOPT_TIMEOUT_MS        = 155
+	CURLOPT_CONNECTTIMEOUT_MS = 156
+	CURLOPT_WRITEFUNCTION     = 20011
+	CURLOPT_HEADERFUNCTION    = 20079
+	CURLOPT_RETURNTRANSFER    = 19913
+	CURLINFO_HEADER_OUT       = 2
+)
+
+// Information of curl_getinfo(): the type (string, long, double) is in
+// the high bits
+const (
+	curlinfoString = 0x100000
+	curlinfoLong   = 0x200000
+	curlinfoDouble = 0x300000
+
+	CURLINFO_EFFECTIVE_URL           = curlinfoString + 1
+	CURLINFO_RESPONSE_CODE           = curlinfoLong + 2
+	CURLINFO_HTTP_CODE               = curlinfoLong + 2
+	CURLINFO_TOTAL_TIME              = curlinfoDouble + 3
+	CURLINFO_NAMELOOKUP_TIME         = curlinfoDouble + 4
+	CURLINFO_CONNECT_TIME            = curlinfoDouble + 5
+	CURLINFO_PRETRANSFER_TIME        = curlinfoDouble + 6
+	CURLINFO_SIZE_UPLOAD             = curlinfoDouble + 7
+	CURLINFO_SIZE_DOWNLOAD           = curlinfoDouble + 8
+	CURLINFO_SPEED_DOWNLOAD          = curlinfoDouble + 9
+	CURLINFO_SPEED_UPLOAD            = curlinfoDouble + 10
+	CURLINFO_HEADER_SIZE             = curlinfoLong + 11
+	CURLINFO_REQUEST_SIZE            = curlinfoLong + 12
+	CURLINFO_CONTENT_LENGTH_DOWNLOAD = curlinfoDouble + 15
+	CURLINFO_CONTENT_LENGTH_UPLOAD   = curlinfoDouble + 16
+	CURLINFO_STARTTRANSFER_TIME      = curlinfoDouble + 17
+	CURLINFO_CONTENT_TYPE            = curlinfoString + 18
+	CURLINFO_REDIRECT_COUNT          = curlinfoLong + 20
+	CURLINFO_PRIVATE                 = curlinfoString + 21
+	CURLINFO_REDIRECT_URL            = curlinfoString + 31
+	CURLINFO_PRIMARY_IP              = curlinfoString + 32
+	CURLINFO_PRIMARY_PORT            = curlinfoLong + 40
+)
+
+// Error codes of curl_errno()
+const (
+	CURLE_OK                       = 0
+	CURLE_UNSUPPORTED_PROTOCOL     = 1
+	CURLE_URL_MALFORMAT            = 3
+	CURLE_COULDNT_RESOLVE_HOST     = 6
+	CURLE_COULDNT_CONNECT          = 7
+	CURLE_HTTP_RETURNED_ERROR      = 22
+	CURLE_WRITE_ERROR              = 23
+	CURLE_OPERATION_TIMEDOUT       = 28
+	CURLE_SSL_CONNECT_ERROR        = 35
+	CURLE_TOO_MANY_REDIRECTS       = 47
+	CURLE_GOT_NOTHING              = 52
+	CURLE_RECV_ERROR               = 56
+	CURLE_PEER_FAILED_VERIFICATION = 60
+)
+
+// Values of CURLOPT_HTTP_VERSION and CURLOPT_HTTPAUTH
+const (
+	CURL_HTTP_VERSION_NONE = 0
+	CURL_HTTP_VERSION_1_0  = 1
+	CURL_HTTP_VERSION_1_1  = 2
+	CURL_HTTP_VERSION_2_0  = 3
+	CURLAUTH_BASIC         = 1
+)
+
+// constantNames lists the constants by name
+var constantNames = map[string]int64{
+	"CURLOPT_PORT": CURLOPT_PORT, "CURLOPT_URL": CURLOPT_URL, "CURLOPT_PROXY": CURLOPT_PROXY,
+	"CURLOPT_USERPWD": CURLOPT_USERPWD, "CURLOPT_TIMEOUT": CURLOPT_TIMEOUT, "CURLOPT_POSTFIELDS": CURLOPT_POSTFIELDS,
+	"CURLOPT_REFERER": CURLOPT_REFERER, "CURLOPT_USERAGENT": CURLOPT_USERAGENT, "CURLOPT_COOKIE": CURLOPT_COOKIE,
+	"CURLOPT_HTTPHEADER": CURLOPT_HTTPHEADER, "CURLOPT_CUSTOMREQUEST": CURLOPT_CUSTOMREQUEST, "CURLOPT_VERBOSE": CURLOPT_VERBOSE,
+	"CURLOPT_HEADER": CURLOPT_HEADER, "CURLOPT_NOPROGRESS": CURLOPT_NOPROGRESS, "CURLOPT_NOBODY": CURLOPT_NOBODY,
+	"CURLOPT_FAILONERROR": CURLOPT_FAILONERROR, "CURLOPT_POST": CURLOPT_POST, "CURLOPT_FOLLOWLOCATION": CURLOPT_FOLLOWLOCATION,
+	"CURLOPT_PUT": CURLOPT_PUT, "CURLOPT_AUTOREFERER": CURLOPT_AUTOREFERER, "CURLOPT_SSL_VERIFYPEER": CURLOPT_SSL_VERIFYPEER,
+	"CURLOPT_MAXREDIRS": CURLOPT_MAXREDIRS, "CURLOPT_CONNECTTIMEOUT": CURLOPT_CONNECTTIMEOUT, "CURLOPT_HTTPGET": CURLOPT_HTTPGET,
+	"CURLOPT_SSL_VERIFYHOST": CURLOPT_SSL_VERIFYHOST, "CURLOPT_HTTP_VERSION": CURLOPT_HTTP_VERSION, "CURLOPT_ENCODING": CURLOPT_ENCODING,
+	"CURLOPT_ACCEPT_ENCODING": CURLOPT_ACCEPT_ENCODING, "CURLOPT_PRIVATE": CURLOPT_PRIVATE, "CURLOPT_HTTPAUTH": CURLOPT_HTTPAUTH,
+	"CURLOPT_TIMEOUT_MS": CURLOPT_TIMEOUT_MS, "CURLOPT_CONNECTTIMEOUT_MS": CURLOPT_CONNECTTIMEOUT_MS,
+	"CURLOPT_WRITEFUNCTION": CURLOPT_WRITEFUNCTION, "CURLOPT_HEADERFUNCTION": CURLOPT_HEADERFUNCTION,
+	"CURLOPT_RETURNTRANSFER": CURLOPT_RETURNTRANSFER, "CURLINFO_HEADER_OUT": CURLINFO_HEADER_OUT,
+
+	"CURLINFO_EFFECTIVE_URL": CURLINFO_EFFECTIVE_URL, "CURLINFO_RESPONSE_CODE": CURLINFO_RESPONSE_CODE,
+	"CURLINFO_HTTP_CODE": CURLINFO_HTTP_CODE, "CURLINFO_TOTAL_TIME": CURLINFO_TOTAL_TIME,
+	"CURLINFO_NAMELOOKUP_TIME": CURLINFO_NAMELOOKUP_TIME, "CURLINFO_CONNECT_TIME": CURLINFO_CONNECT_TIME,
+	"CURLINFO_PRETRANSFER_TIME": CURLINFO_PRETRANSFER_TIME, "CURLINFO_SIZE_UPLOAD": CURLINFO_SIZE_UPLOAD,
+	"CURLINFO_SIZE_DOWNLOAD": CURLINFO_SIZE_DOWNLOAD, "CURLINFO_SPEED_DOWNLOAD": CURLINFO_SPEED_DOWNLOAD,
+	"CURLINFO_SPEED_UPLOAD": CURLINFO_SPEED_UPLOAD, "CURLINFO_HEADER_SIZE": CURLINFO_HEADER_SIZE,
+	"CURLINFO_REQUEST_SIZE": CURLINFO_REQUEST_SIZE, "CURLINFO_CONTENT_LENGTH_DOWNLOAD": CURLINFO_CONTENT_LENGTH_DOWNLOAD,
+	"CURLINFO_CONTENT_LENGTH_UPLOAD": CURLINFO_CONTENT_LENGTH_UPLOAD, "CURLINFO_STARTTRANSFER_TIME": CURLINFO_STARTTRANSFER_TIME,
+	"CURLINFO_CONTENT_TYPE": CURLINFO_CONTENT_TYPE, "CURLINFO_REDIRECT_COUNT": CURLINFO_REDIRECT_COUNT,
+	"CURLINFO_PRIVATE": CURLINFO_PRIVATE, "CURLINFO_REDIRECT_URL": CURLINFO_REDIRECT_URL,
+	"CURLINFO_PRIMARY_IP": CURLINFO_PRIMARY_IP, "CURLINFO_PRIMARY_PORT": CURLINFO_PRIMARY_PORT,
+
+	"CURLE_OK": CURLE_OK, "CURLE_UNSUPPORTED_PROTOCOL": CURLE_UNSUPPORTED_PROTOCOL, "CURLE_URL_MALFORMAT": CURLE_URL_MALFORMAT,
+	"CURLE_COULDNT_RESOLVE_HOST": CURLE_COULDNT_RESOLVE_HOST, "CURLE_COULDNT_CONNECT": CURLE_COULDNT_CONNECT,
+	"CURLE_HTTP_RETURNED_ERROR": CURLE_HTTP_RETURNED_ERROR, "CURLE_WRITE_ERROR": CURLE_WRITE_ERROR,
+	"CURLE_OPERATION_TIMEDOUT": CURLE_OPERATION_TIMEDOUT, "CURLE_SSL_CONNECT_ERROR": CURLE_SSL_CONNECT_ERROR,
+	"CURLE_TOO_MANY_REDIRECTS": CURLE_TOO_MANY_REDIRECTS, "CURLE_GOT_NOTHING": CURLE_GOT_NOTHING,
+	"CURLE_RECV_ERROR": CURLE_RECV_ERROR, "CURLE_PEER_FAILED_VERIFICATION": CURLE_PEER_FAILED_VERIFICATION,
+
+	"CURL_HTTP_VERSION_NONE": CURL_HTTP_VERSION_NONE, "CURL_HTTP_VERSION_1_0": CURL_HTTP_VERSION_1_0,
+	"CURL_HTTP_VERSION_1_1": CURL_HTTP_VERSION_1_1, "CURL_HTTP_VERSION_2_0": CURL_HTTP_VERSION_2_0,
+	"CURLAUTH_BASIC": CURLAUTH_BASIC,
+}
+
+// Constants returns the curl constants
+func Constants() map[string]*types.Value {
+	constants := make(map[string]*types.Value, len(constantNames))
+	for name, value := range constantNames {
+		constants[name] = types.NewInt(value)
+	}
+	return constants
+}
+
+// errorMessages are the descriptions of curl_strerror()
+var errorMessages = map[int]string{
+	CURLE_OK:                       "No error",
+	CURLE_UNSUPPORTED_PROTOCOL:     "Unsupported protocol",
+	CURLE_URL_MALFORMAT:            "URL using bad/illegal format or missing URL",
+	CURLE_COULDNT_RESOLVE_HOST:     "Couldn't resolve host name",
+	CURLE_COULDNT_CONNECT:          "Couldn't connect to server",
+	CURLE_HTTP_RETURNED_ERROR:      "HTTP response code said error",
+	CURLE_WRITE_ERROR:              "Failed writing received data to disk/application",
+	CURLE_OPERATION_TIMEDOUT:       "Timeout was reached",
+	CURLE_SSL_CONNECT_ERROR:        "SSL connect error",
+	CURLE_TOO_MANY_REDIRECTS:       "Number of redirects hit maximum amount",
+	CURLE_GOT_NOTHING:              "Server returned nothing (no headers, no data)",
+	CURLE_RECV_ERROR:               "Failure when receiving data from the peer",
+	CURLE_PEER_FAILED_VERIFICATION: "SSL peer certificate or SSH remote key was not OK",
+}
+
+// Strerror returns the description of an error code, as curl_strerror()
+// does; null for unknown codes
+func Strerror(code *types.Value) *types.Value {
+	message, ok := errorMessages[int(code.ToInt())]
+	if !ok {
+		return types.NewNull()
+	}
+	return types.NewString(message)
+}
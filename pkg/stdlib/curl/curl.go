@@ -0,0 +1,367 @@
+package curl
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// Error is an error thrown by a curl function, such as the ValueError of
+// an unknown option
+type Error struct {
+	Class   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// defaultMaxRedirects is libcurl's limit of followed redirects
+const defaultMaxRedirects = 30
+
+// ============================================================================
+// Handle
+// ============================================================================
+
+// Handle is a curl easy handle: the options of a transfer and the
+// information about the last one
+type Handle struct {
+	url            string
+	method         string // CURLOPT_CUSTOMREQUEST
+	post           bool
+	put            bool
+	nobody         bool
+	fields         *types.Value
+	headers        []string
+	returnTransfer bool
+	includeHeader  bool
+	headerOut      bool
+	followLocation bool
+	autoReferer    bool
+	failOnError    bool
+	maxRedirects   int64
+	timeout        time.Duration
+	connectTimeout time.Duration
+	userAgent      string
+	referer        string
+	cookie         string
+	userPwd        string
+	encoding       *string
+	proxy          string
+	port           int64
+	verifyPeer     bool
+	verifyHost     bool
+	writeFunction  *types.Value
+	headerFunction *types.Value
+	private        *types.Value
+
+	errno  int
+	errmsg string
+	info   transferInfo
+}
+
+// NewHandle returns a handle with libcurl's defaults, transferring url
+// if it is not empty
+func NewHandle(url string) *Handle {
+	h := &Handle{}
+	h.Reset()
+	h.url = url
+	h.info.url = url
+	return h
+}
+
+// Reset restores the default options, as curl_reset() does
+func (h *Handle) Reset() {
+	*h = Handle{
+		maxRedirects: defaultMaxRedirects,
+		verifyPeer:   true,
+		verifyHost:   true,
+		private:      types.NewNull(),
+		info:         transferInfo{contentLength: -1},
+	}
+}
+
+// Copy returns a handle with the same options, as curl_copy_handle() does
+func (h *Handle) Copy() *Handle {
+	copied := *h
+	copied.headers = append([]string(nil), h.headers...)
+	return &copied
+}
+
+// Errno returns the error code of the last transfer
+func (h *Handle) Errno() int { return h.errno }
+
+// Error returns the error message of the last transfer; empty if it
+// succeeded
+func (h *Handle) Error() string { return h.errmsg }
+
+// fail records the error of a transfer
+func (h *Handle) fail(code int, format string, args ...any) {
+	h.errno = code
+	h.errmsg = fmt.Sprintf(format, args...)
+}
+
+// SetOpt sets an option, as curl_setopt() does. Options curl knows but
+// that have no effect here are accepted and ignored.
+func (h *Handle) SetOpt(option int64, value *types.Value) (bool, error) {
+	switch option {
+	case CURLOPT_URL:
+		h.url = value.ToString()
+	case CURLOPT_CUSTOMREQUEST:
+		h.method = value.ToString()
+		if value.IsNull() {
+			h.method = ""
+		}
+	case CURLOPT_POST:
+		h.post = value.ToBool()
+		if h.post {
+			h.put, h.nobody = false, false
+		}
+	case CURLOPT_PUT:
+		h.put = value.ToBool()
+		if h.put {
+			h.post, h.nobody = false, false
+		}
+	case CURLOPT_HTTPGET:
+		if value.ToBool() {
+			h.post, h.put, h.nobody = false, false, false
+		}
+	case CURLOPT_NOBODY:
+		h.nobody = value.ToBool()
+	case CURLOPT_POSTFIELDS:
+		// Setting the fields implies a POST, as in libcurl
+		h.fields = value.Copy()
+		h.post, h.put, h.nobody = true, false, false
+	case CURLOPT_HTTPHEADER:
+		if value.Type() != types.TypeArray {
+			return false, &Error{Class: "TypeError", Message: "curl_setopt(): The CURLOPT_HTTPHEADER option must have an array value"}
+		}
+		h.headers = nil
+		value.ToArray().Each(func(_, header *types.Value) bool {
+			h.headers = append(h.headers, header.ToString())
+			return true
+		})
+	case CURLOPT_RETURNTRANSFER:
+		h.returnTransfer = value.ToBool()
+	case CURLOPT_HEADER:
+		h.includeHeader = value.ToBool()
+	case CURLINFO_HEADER_OUT:
+		h.headerOut = value.ToBool()
+	case CURLOPT_FOLLOWLOCATION:
+		h.followLocation = value.ToBool()
+	case CURLOPT_AUTOREFERER:
+		h.autoReferer = value.ToBool()
+	case CURLOPT_FAILONERROR:
+		h.failOnError = value.ToBool()
+	case CURLOPT_MAXREDIRS:
+		h.maxRedirects = value.ToInt()
+	case CURLOPT_TIMEOUT:
+		h.timeout = time.Duration(value.ToInt()) * time.Second
+	case CURLOPT_TIMEOUT_MS:
+		h.timeout = time.Duration(value.ToInt()) * time.Millisecond
+	case CURLOPT_CONNECTTIMEOUT:
+		h.connectTimeout = time.Duration(value.ToInt()) * time.Second
+	case CURLOPT_CONNECTTIMEOUT_MS:
+		h.connectTimeout = time.Duration(value.ToInt()) * time.Millisecond
+	case CURLOPT_USERAGENT:
+		h.userAgent = value.ToString()
+	case CURLOPT_REFERER:
+		h.referer = value.ToString()
+	case CURLOPT_COOKIE:
+		h.cookie = value.ToString()
+	case CURLOPT_USERPWD:
+		h.userPwd = value.ToString()
+	case CURLOPT_ENCODING:
+		if value.IsNull() {
+			h.encoding = nil
+		} else {
+			encoding := value.ToString()
+			h.encoding = &encoding
+		}
+	case CURLOPT_PROXY:
+		h.proxy = value.ToString()
+	case CURLOPT_PORT:
+		h.port = value.ToInt()
+	case CURLOPT_SSL_VERIFYPEER:
+		h.verifyPeer = value.ToBool()
+	case CURLOPT_SSL_VERIFYHOST:
+		h.verifyHost = value.ToInt() != 0
+	case CURLOPT_WRITEFUNCTION:
+		h.writeFunction = callbackOf(value)
+	case CURLOPT_HEADERFUNCTION:
+		h.headerFunction = callbackOf(value)
+	case CURLOPT_PRIVATE:
+		h.private = value.Copy()
+	case CURLOPT_VERBOSE, CURLOPT_NOPROGRESS, CURLOPT_HTTP_VERSION, CURLOPT_HTTPAUTH:
+	default:
+		return false, &Error{Class: "ValueError", Message: "curl_setopt(): Argument #2 ($option) is not a valid cURL option"}
+	}
+	return true, nil
+}
+
+// callbackOf returns the callable of a callback option; nil unsets it
+func callbackOf(value *types.Value) *types.Value {
+	if value.IsNull() {
+		return nil
+	}
+	return value
+}
+
+// ============================================================================
+// Transfer Information
+// ============================================================================
+
+// transferInfo is what curl_getinfo() reports about the last transfer
+type transferInfo struct {
+	url           string
+	contentType   *string
+	code          int64
+	headerSize    int64
+	requestSize   int64
+	redirectCount int64
+	redirectURL   string
+	total         float64
+	lookup        float64
+	connect       float64
+	startTransfer float64
+	sizeUpload    float64
+	sizeDownload  float64
+	contentLength float64
+	primaryIP     string
+	primaryPort   int64
+	requestHeader string
+}
+
+// GetInfo returns one item of information about the last transfer, as
+// curl_getinfo() does with an option; false for unknown options
+func (h *Handle) GetInfo(option int64) *types.Value {
+	info := &h.info
+	switch option {
+	case CURLINFO_EFFECTIVE_URL:
+		return types.NewString(info.url)
+	case CURLINFO_RESPONSE_CODE:
+		return types.NewInt(info.code)
+	case CURLINFO_TOTAL_TIME:
+		return types.NewFloat(info.total)
+	case CURLINFO_NAMELOOKUP_TIME:
+		return types.NewFloat(info.lookup)
+	case CURLINFO_CONNECT_TIME:
+		return types.NewFloat(info.connect)
+	case CURLINFO_PRETRANSFER_TIME:
+		return types.NewFloat(info.connect)
+	case CURLINFO_STARTTRANSFER_TIME:
+		return types.NewFloat(info.startTransfer)
+	case CURLINFO_SIZE_UPLOAD:
+		return types.NewFloat(info.sizeUpload)
+	case CURLINFO_SIZE_DOWNLOAD:
+		return types.NewFloat(info.sizeDownload)
+	case CURLINFO_SPEED_DOWNLOAD:
+		return types.NewFloat(speed(info.sizeDownload, info.total))
+	case CURLINFO_SPEED_UPLOAD:
+		return types.NewFloat(speed(info.sizeUpload, info.total))
+	case CURLINFO_HEADER_SIZE:
+		return types.NewInt(info.headerSize)
+	case CURLINFO_REQUEST_SIZE:
+		return types.NewInt(info.requestSize)
+	case CURLINFO_CONTENT_LENGTH_DOWNLOAD:
+		return types.NewFloat(info.contentLength)
+	case CURLINFO_CONTENT_LENGTH_UPLOAD:
+		return types.NewFloat(-1)
+	case CURLINFO_CONTENT_TYPE:
+		if info.contentType == nil {
+			return types.NewNull()
+		}
+		return types.NewString(*info.contentType)
+	case CURLINFO_REDIRECT_COUNT:
+		return types.NewInt(info.redirectCount)
+	case CURLINFO_REDIRECT_URL:
+		if info.redirectURL == "" {
+			return types.NewBool(false)
+		}
+		return types.NewString(info.redirectURL)
+	case CURLINFO_PRIMARY_IP:
+		return types.NewString(info.primaryIP)
+	case CURLINFO_PRIMARY_PORT:
+		return types.NewInt(info.primaryPort)
+	case CURLINFO_PRIVATE:
+		return h.private
+	case CURLINFO_HEADER_OUT:
+		if !h.headerOut {
+			return types.NewBool(false)
+		}
+		return types.NewString(info.requestHeader)
+	}
+	return types.NewBool(false)
+}
+
+// Info returns the information about the last transfer as an array, as
+// curl_getinfo() does without an option
+func (h *Handle) Info() *types.Array {
+	info := types.NewEmptyArray()
+	set := func(key string, option int64) {
+		info.Set(types.NewString(key), h.GetInfo(option))
+	}
+	set("url", CURLINFO_EFFECTIVE_URL)
+	set("content_type", CURLINFO_CONTENT_TYPE)
+	set("http_code", CURLINFO_HTTP_CODE)
+	set("header_size", CURLINFO_HEADER_SIZE)
+	set("request_size", CURLINFO_REQUEST_SIZE)
+	info.Set(types.NewString("filetime"), types.NewInt(-1))
+	info.Set(types.NewString("ssl_verify_result"), types.NewInt(0))
+	set("redirect_count", CURLINFO_REDIRECT_COUNT)
+	set("total_time", CURLINFO_TOTAL_TIME)
+	set("namelookup_time", CURLINFO_NAMELOOKUP_TIME)
+	set("connect_time", CURLINFO_CONNECT_TIME)
+	set("pretransfer_time", CURLINFO_PRETRANSFER_TIME)
+	set("size_upload", CURLINFO_SIZE_UPLOAD)
+	set("size_download", CURLINFO_SIZE_DOWNLOAD)
+	set("speed_download", CURLINFO_SPEED_DOWNLOAD)
+	set("speed_upload", CURLINFO_SPEED_UPLOAD)
+	set("download_content_length", CURLINFO_CONTENT_LENGTH_DOWNLOAD)
+	set("upload_content_length", CURLINFO_CONTENT_LENGTH_UPLOAD)
+	set("starttransfer_time", CURLINFO_STARTTRANSFER_TIME)
+	info.Set(types.NewString("redirect_time"), types.NewFloat(0))
+	info.Set(types.NewString("redirect_url"), types.NewString(h.info.redirectURL))
+	set("primary_ip", CURLINFO_PRIMARY_IP)
+	set("primary_port", CURLINFO_PRIMARY_PORT)
+	if h.headerOut {
+		set("request_header", CURLINFO_HEADER_OUT)
+	}
+	return info
+}
+
+// speed returns bytes per second of a transfer
+func speed(size, seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	return size / seconds
+}
+
+// ============================================================================
+// Version
+// ============================================================================
+
+// Version is the libcurl version reported by curl_version()
+const Version = "8.4.0"
+
+// VersionInfo returns the array of curl_version()
+func VersionInfo(host string) *types.Array {
+	info := types.NewEmptyArray()
+	set := func(key string, value *types.Value) { info.Set(types.NewString(key), value) }
+	set("version_number", types.NewInt(0x080400))
+	set("age", types.NewInt(10))
+	set("features", types.NewInt(0))
+	set("ssl_version_number", types.NewInt(0))
+	set("version", types.NewString(Version))
+	set("host", types.NewString(host))
+	set("ssl_version", types.NewString("Go crypto/tls"))
+	set("libz_version", types.NewString(""))
+	protocols := types.NewEmptyArray()
+	protocols.Append(types.NewString("http"))
+	protocols.Append(types.NewString("https"))
+	set("protocols", types.NewArray(protocols))
+	return info
+}
@@ -0,0 +1,232 @@
+package curl
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krizos/php-go/pkg/stdlib"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// newTestServer answers /echo with the request, redirects /redirect/N
+// N times and fails /missing
+func newTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "%s %s ua=%q type=%q x=%q body=%s", r.Method, r.URL.RequestURI(),
+			r.Header.Get("User-Agent"), r.Header.Get("Content-Type"), r.Header.Get("X-Test"), body)
+	})
+	mux.HandleFunc("/redirect/", func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/redirect/"), "%d", &n)
+		if n <= 1 {
+			http.Redirect(w, r, "/echo", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/redirect/%d", n-1), http.StatusFound)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusNotFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func setopts(t *testing.T, h *Handle, options map[int64]*types.Value) {
+	t.Helper()
+	for option, value := range options {
+		if ok, err := h.SetOpt(option, value); !ok || err != nil {
+			t.Fatalf("SetOpt(%d) = %v, %v", option, ok, err)
+		}
+	}
+}
+
+func TestExecReturnTransfer(t *testing.T) {
+	server := newTestServer(t)
+	h := NewHandle(server.URL + "/echo?q=1")
+	setopts(t, h, map[int64]*types.Value{CURLOPT_RETURNTRANSFER: types.NewBool(true)})
+
+	result, err := h.Exec(Env{})
+	if err != nil || result.ToString() != `GET /echo?q=1 ua="" type="" x="" body=` {
+		t.Fatalf("Exec = %v, %v (%s)", result, err, h.Error())
+	}
+	if code := h.GetInfo(CURLINFO_HTTP_CODE); code.ToInt() != 200 {
+		t.Errorf("http_code = %v", code)
+	}
+	if ct := h.GetInfo(CURLINFO_CONTENT_TYPE); ct.ToString() != "text/plain" {
+		t.Errorf("content_type = %v", ct)
+	}
+	if h.GetInfo(CURLINFO_PRIMARY_IP).ToString() != "127.0.0.1" || h.Errno() != CURLE_OK {
+		t.Errorf("info = %v, errno %d", h.Info(), h.Errno())
+	}
+}
+
+func TestExecPostAndHeaders(t *testing.T) {
+	server := newTestServer(t)
+	h := NewHandle(server.URL + "/echo")
+	headers := types.NewEmptyArray()
+	headers.Append(types.NewString("X-Test: yes"))
+	setopts(t, h, map[int64]*types.Value{
+		CURLOPT_RETURNTRANSFER: types.NewBool(true),
+		CURLOPT_POSTFIELDS:     types.NewString("a=1&b=2"),
+		CURLOPT_HTTPHEADER:     types.NewArray(headers),
+		CURLOPT_USERAGENT:      types.NewString("tester"),
+		CURLINFO_HEADER_OUT:    types.NewBool(true),
+	})
+
+	result, _ := h.Exec(Env{})
+	want := `POST /echo ua="tester" type="application/x-www-form-urlencoded" x="yes" body=a=1&b=2`
+	if result.ToString() != want {
+		t.Errorf("Exec = %q, want %q", result.ToString(), want)
+	}
+	if sent := h.GetInfo(CURLINFO_HEADER_OUT).ToString(); !strings.HasPrefix(sent, "POST /echo HTTP/1.1\r\n") || !strings.Contains(sent, "X-Test: yes\r\n") {
+		t.Errorf("request_header = %q", sent)
+	}
+
+	fields := types.NewEmptyArray()
+	fields.Set(types.NewString("name"), types.NewString("value"))
+	setopts(t, h, map[int64]*types.Value{CURLOPT_POSTFIELDS: types.NewArray(fields)})
+	result, _ = h.Exec(Env{})
+	if !strings.Contains(result.ToString(), `type="multipart/form-data; boundary=`) || !strings.Contains(result.ToString(), "value") {
+		t.Errorf("array fields should be sent as multipart: %q", result.ToString())
+	}
+}
+
+func TestExecRedirects(t *testing.T) {
+	server := newTestServer(t)
+	h := NewHandle(server.URL + "/redirect/2")
+	setopts(t, h, map[int64]*types.Value{CURLOPT_RETURNTRANSFER: types.NewBool(true)})
+
+	// Without CURLOPT_FOLLOWLOCATION the redirect is reported
+	h.Exec(Env{})
+	if h.GetInfo(CURLINFO_HTTP_CODE).ToInt() != 302 || h.GetInfo(CURLINFO_REDIRECT_URL).ToString() != server.URL+"/redirect/1" {
+		t.Errorf("info = %v", h.Info())
+	}
+
+	setopts(t, h, map[int64]*types.Value{CURLOPT_FOLLOWLOCATION: types.NewBool(true), CURLOPT_POSTFIELDS: types.NewString("x")})
+	result, _ := h.Exec(Env{})
+	if !strings.HasPrefix(result.ToString(), "GET /echo") || h.GetInfo(CURLINFO_REDIRECT_COUNT).ToInt() != 2 {
+		t.Errorf("Exec = %q, redirects %v", result.ToString(), h.GetInfo(CURLINFO_REDIRECT_COUNT))
+	}
+	if url := h.GetInfo(CURLINFO_EFFECTIVE_URL).ToString(); url != server.URL+"/echo" {
+		t.Errorf("effective URL = %q", url)
+	}
+
+	setopts(t, h, map[int64]*types.Value{CURLOPT_MAXREDIRS: types.NewInt(1)})
+	if result, _ := h.Exec(Env{}); result.ToBool() || h.Errno() != CURLE_TOO_MANY_REDIRECTS || h.Error() != "Maximum (1) redirects followed" {
+		t.Errorf("Exec = %v, %d %q", result, h.Errno(), h.Error())
+	}
+}
+
+func TestExecHeaderAndOutput(t *testing.T) {
+	server := newTestServer(t)
+	h := NewHandle(server.URL + "/missing")
+	setopts(t, h, map[int64]*types.Value{CURLOPT_HEADER: types.NewBool(true)})
+
+	var output string
+	result, _ := h.Exec(Env{Output: func(data string) { output += data }})
+	if !result.ToBool() || !strings.HasPrefix(output, "HTTP/1.1 404 Not Found\r\n") || !strings.HasSuffix(output, "\r\n\r\ngone\n") {
+		t.Errorf("Exec = %v, output %q", result, output)
+	}
+	if size := h.GetInfo(CURLINFO_HEADER_SIZE).ToInt(); size != int64(strings.Index(output, "\r\n\r\n")+4) {
+		t.Errorf("header_size = %d", size)
+	}
+
+	setopts(t, h, map[int64]*types.Value{CURLOPT_FAILONERROR: types.NewBool(true)})
+	if result, _ := h.Exec(Env{}); result.ToBool() || h.Errno() != CURLE_HTTP_RETURNED_ERROR || h.Error() != "The requested URL returned error: 404" {
+		t.Errorf("Exec = %v, %d %q", result, h.Errno(), h.Error())
+	}
+}
+
+func TestExecCallbacks(t *testing.T) {
+	server := newTestServer(t)
+	h := NewHandle(server.URL + "/echo")
+	setopts(t, h, map[int64]*types.Value{
+		CURLOPT_WRITEFUNCTION:  types.NewString("write"),
+		CURLOPT_HEADERFUNCTION: types.NewString("header"),
+	})
+
+	var written string
+	var lines []string
+	caller := stdlib.CallerFunc(func(callable *types.Value, args []*types.Value) (*types.Value, error) {
+		data := args[1].ToString()
+		if callable.ToString() == "header" {
+			lines = append(lines, data)
+		} else {
+			written += data
+		}
+		return types.NewInt(int64(len(data))), nil
+	})
+	result, err := h.Exec(Env{Caller: caller})
+	if err != nil || !result.ToBool() || !strings.HasPrefix(written, "GET /echo") {
+		t.Fatalf("Exec = %v, %v, written %q", result, err, written)
+	}
+	if len(lines) < 3 || lines[0] != "HTTP/1.1 200 OK\r\n" || lines[len(lines)-1] != "\r\n" {
+		t.Errorf("header lines = %q", lines)
+	}
+
+	short := stdlib.CallerFunc(func(*types.Value, []*types.Value) (*types.Value, error) { return types.NewInt(0), nil })
+	if result, _ := h.Exec(Env{Caller: short}); result.ToBool() || h.Errno() != CURLE_WRITE_ERROR {
+		t.Errorf("a callback handling less data aborts: %v, %d", result, h.Errno())
+	}
+}
+
+func TestExecErrors(t *testing.T) {
+	server := newTestServer(t)
+	tests := []struct {
+		url     string
+		options map[int64]*types.Value
+		errno   int
+		prefix  string
+	}{
+		{"", nil, CURLE_URL_MALFORMAT, "No URL set"},
+		{"ftp://example.com/", nil, CURLE_UNSUPPORTED_PROTOCOL, `Protocol "ftp" not supported`},
+		{"http://127.0.0.1:1/", nil, CURLE_COULDNT_CONNECT, "Failed to connect to 127.0.0.1 port 1 after "},
+		{server.URL + "/slow", map[int64]*types.Value{CURLOPT_TIMEOUT_MS: types.NewInt(50)}, CURLE_OPERATION_TIMEDOUT, "Operation timed out after "},
+	}
+	for _, tt := range tests {
+		h := NewHandle(tt.url)
+		setopts(t, h, tt.options)
+		result, err := h.Exec(Env{})
+		if err != nil || result.ToBool() || h.Errno() != tt.errno || !strings.HasPrefix(h.Error(), tt.prefix) {
+			t.Errorf("%q: Exec = %v, %v; errno %d %q", tt.url, result, err, h.Errno(), h.Error())
+		}
+	}
+
+	h := NewHandle("")
+	if _, err := h.SetOpt(99999, types.NewInt(1)); err == nil || err.(*Error).Class != "ValueError" {
+		t.Errorf("unknown options are a ValueError: %v", err)
+	}
+	if _, err := h.SetOpt(CURLOPT_HTTPHEADER, types.NewString("X: y")); err == nil || err.(*Error).Class != "TypeError" {
+		t.Errorf("CURLOPT_HTTPHEADER needs an array: %v", err)
+	}
+	if Strerror(types.NewInt(CURLE_COULDNT_RESOLVE_HOST)).ToString() != "Couldn't resolve host name" || !Strerror(types.NewInt(9999)).IsNull() {
+		t.Errorf("Strerror")
+	}
+}
+
+func TestCopyAndReset(t *testing.T) {
+	h := NewHandle("http://example.com/")
+	h.SetOpt(CURLOPT_HTTPHEADER, types.NewArray(types.NewArrayFromMap(map[interface{}]*types.Value{0: types.NewString("A: b")})))
+	h.SetOpt(CURLOPT_PRIVATE, types.NewString("mine"))
+	copied := h.Copy()
+	copied.headers[0] = "changed"
+	if h.headers[0] != "A: b" || copied.url != h.url || copied.GetInfo(CURLINFO_PRIVATE).ToString() != "mine" {
+		t.Errorf("Copy shares state: %v / %v", h.headers, copied.headers)
+	}
+	h.Reset()
+	if h.url != "" || h.headers != nil || !h.GetInfo(CURLINFO_PRIVATE).IsNull() {
+		t.Errorf("Reset keeps options")
+	}
+}
@@ -0,0 +1,469 @@
+package curl
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krizos/php-go/pkg/stdlib"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// Env is what a transfer needs from the running script
+type Env struct {
+	// Caller calls CURLOPT_WRITEFUNCTION and CURLOPT_HEADERFUNCTION
+	Caller stdlib.Caller
+	// Handle is the CurlHandle object passed to the callbacks
+	Handle *types.Value
+	// Output receives the response when CURLOPT_RETURNTRANSFER is off
+	Output func(data string)
+}
+
+// errAborted stops a transfer whose callback rejected the data; the
+// error code and message are already recorded
+var errAborted = errors.New("transfer aborted")
+
+// request is one HTTP request of a transfer, redirects included
+type request struct {
+	method      string
+	target      *url.URL
+	body        []byte
+	contentType string
+}
+
+// Exec performs the transfer, as curl_exec() does: the response when
+// CURLOPT_RETURNTRANSFER is set, true when it went elsewhere, false on
+// errors. Errors thrown by callbacks are returned.
+func (h *Handle) Exec(env Env) (*types.Value, error) {
+	h.errno, h.errmsg = CURLE_OK, ""
+	h.info = transferInfo{contentLength: -1}
+	start := time.Now()
+	defer func() { h.info.total = time.Since(start).Seconds() }()
+
+	req, ok := h.firstRequest()
+	if !ok {
+		return types.NewBool(false), nil
+	}
+	client := h.client()
+
+	var output strings.Builder
+	for {
+		h.info.url = req.target.String()
+		resp, err := h.do(client, req, start)
+		if err != nil {
+			h.failRequest(err, req.target, start, 0)
+			return types.NewBool(false), nil
+		}
+
+		header := headerBlock(resp)
+		h.info.code = int64(resp.StatusCode)
+		h.info.headerSize += int64(len(header))
+		if err := h.deliverHeader(env, header); err != nil {
+			resp.Body.Close()
+			return h.aborted(err)
+		}
+		if h.includeHeader {
+			output.WriteString(header)
+		}
+
+		location := resp.Header.Get("Location")
+		next, redirect := h.redirect(req, resp.StatusCode, location)
+		if redirect && h.followLocation {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if h.maxRedirects >= 0 && h.info.redirectCount >= h.maxRedirects {
+				h.fail(CURLE_TOO_MANY_REDIRECTS, "Maximum (%d) redirects followed", h.maxRedirects)
+				return types.NewBool(false), nil
+			}
+			h.info.redirectCount++
+			req = next
+			continue
+		}
+		if redirect {
+			h.info.redirectURL = next.target.String()
+		}
+
+		if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+			h.info.contentType = &contentType
+		}
+		h.info.contentLength = float64(resp.ContentLength)
+		if h.failOnError && resp.StatusCode >= 400 {
+			resp.Body.Close()
+			h.fail(CURLE_HTTP_RETURNED_ERROR, "The requested URL returned error: %d", resp.StatusCode)
+			return types.NewBool(false), nil
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		h.info.sizeDownload = float64(len(body))
+		if err != nil {
+			h.failRequest(err, req.target, start, len(body))
+			return types.NewBool(false), nil
+		}
+		if h.writeFunction != nil {
+			if err := h.callback(env, h.writeFunction, header, string(body), "Failure writing output to destination"); err != nil {
+				return h.aborted(err)
+			}
+			return types.NewBool(true), nil
+		}
+		output.Write(body)
+		break
+	}
+
+	if h.returnTransfer {
+		return types.NewString(output.String()), nil
+	}
+	if env.Output != nil {
+		env.Output(output.String())
+	}
+	return types.NewBool(true), nil
+}
+
+// aborted returns the result of a transfer stopped by a callback
+func (h *Handle) aborted(err error) (*types.Value, error) {
+	if err == errAborted {
+		return types.NewBool(false), nil
+	}
+	return nil, err
+}
+
+// firstRequest builds the request of the options, recording an error
+// for unusable URLs
+func (h *Handle) firstRequest() (request, bool) {
+	raw := strings.TrimSpace(h.url)
+	if raw == "" {
+		h.fail(CURLE_URL_MALFORMAT, "No URL set")
+		return request{}, false
+	}
+	// Like libcurl, URLs without a scheme are taken as HTTP
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	target, err := url.Parse(raw)
+	if err != nil {
+		h.fail(CURLE_URL_MALFORMAT, "URL rejected: Malformed input to a URL function")
+		return request{}, false
+	}
+	target.Scheme = strings.ToLower(target.Scheme)
+	if target.Scheme != "http" && target.Scheme != "https" {
+		h.fail(CURLE_UNSUPPORTED_PROTOCOL, "Protocol \"%s\" not supported", target.Scheme)
+		return request{}, false
+	}
+	if target.Host == "" {
+		h.fail(CURLE_URL_MALFORMAT, "URL rejected: No host part in the URL")
+		return request{}, false
+	}
+	if h.port > 0 {
+		target.Host = net.JoinHostPort(target.Hostname(), strconv.FormatInt(h.port, 10))
+	}
+
+	req := request{method: "GET", target: target}
+	switch {
+	case h.nobody:
+		req.method = "HEAD"
+	case h.post:
+		req.method = "POST"
+		req.body, req.contentType = encodeFields(h.fields)
+	case h.put:
+		req.method = "PUT"
+	}
+	if h.method != "" {
+		req.method = h.method
+	}
+	return req, true
+}
+
+// encodeFields encodes CURLOPT_POSTFIELDS: strings are sent as they are,
+// arrays as multipart/form-data
+func encodeFields(fields *types.Value) ([]byte, string) {
+	if fields == nil {
+		return nil, "application/x-www-form-urlencoded"
+	}
+	if fields.Type() != types.TypeArray {
+		return []byte(fields.ToString()), "application/x-www-form-urlencoded"
+	}
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	fields.ToArray().Each(func(key, value *types.Value) bool {
+		writer.WriteField(key.ToString(), value.ToString())
+		return true
+	})
+	writer.Close()
+	return body.Bytes(), writer.FormDataContentType()
+}
+
+// redirect returns the request following a 3xx response with a Location
+// header. As in libcurl, a POST answered by 301, 302 or 303 becomes a GET.
+func (h *Handle) redirect(req request, status int, location string) (request, bool) {
+	switch status {
+	case 301, 302, 303, 307, 308:
+	default:
+		return req, false
+	}
+	if location == "" {
+		return req, false
+	}
+	target, err := req.target.Parse(location)
+	if err != nil {
+		return req, false
+	}
+	next := request{method: req.method, target: target, body: req.body, contentType: req.contentType}
+	if (status == 303 && req.method != "HEAD") || (status <= 302 && req.method == "POST") {
+		next.method, next.body, next.contentType = "GET", nil, ""
+	}
+	if h.method != "" && status != 303 {
+		next.method = h.method
+	}
+	return next, true
+}
+
+// client returns an HTTP client for the options; redirects are followed
+// by Exec so they can be counted and reported
+func (h *Handle) client() *http.Client {
+	dialer := &net.Dialer{Timeout: h.connectTimeout}
+	transport := &http.Transport{
+		DialContext:        dialer.DialContext,
+		TLSClientConfig:    &tls.Config{InsecureSkipVerify: !h.verifyPeer || !h.verifyHost},
+		DisableCompression: h.encoding == nil,
+		DisableKeepAlives:  true,
+	}
+	if h.proxy != "" {
+		proxy := h.proxy
+		if !strings.Contains(proxy, "://") {
+			proxy = "http://" + proxy
+		}
+		if proxyURL, err := url.Parse(proxy); err == nil {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   h.timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// do sends one request, recording its size, headers and timings
+func (h *Handle) do(client *http.Client, req request, start time.Time) (*http.Response, error) {
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+	httpReq, err := http.NewRequest(req.method, req.target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	// libcurl sends Accept and no User-Agent unless told otherwise; the
+	// headers of CURLOPT_HTTPHEADER replace the defaults
+	headers := [][2]string{{"Accept", "*/*"}}
+	if h.userAgent != "" {
+		headers = append(headers, [2]string{"User-Agent", h.userAgent})
+	}
+	if h.referer != "" {
+		headers = append(headers, [2]string{"Referer", h.referer})
+	}
+	if h.cookie != "" {
+		headers = append(headers, [2]string{"Cookie", h.cookie})
+	}
+	if req.contentType != "" {
+		headers = append(headers, [2]string{"Content-Type", req.contentType})
+	}
+	for _, line := range h.headers {
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		headers = setHeader(headers, strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	httpReq.Header.Set("User-Agent", "")
+	for _, header := range headers {
+		httpReq.Header.Set(header[0], header[1])
+	}
+	if h.userPwd != "" {
+		user, password, _ := strings.Cut(h.userPwd, ":")
+		httpReq.SetBasicAuth(user, password)
+		headers = append(headers, [2]string{"Authorization", httpReq.Header.Get("Authorization")})
+	}
+	// The transport requests and decodes gzip itself when an encoding is
+	// accepted
+	if h.encoding != nil {
+		headers = append(headers, [2]string{"Accept-Encoding", "gzip"})
+	}
+
+	sent := requestHeader(httpReq, headers)
+	h.info.requestSize += int64(len(sent))
+	h.info.requestHeader = sent
+	h.info.sizeUpload = float64(len(req.body))
+
+	trace := &httptrace.ClientTrace{
+		DNSDone:     func(httptrace.DNSDoneInfo) { h.info.lookup = time.Since(start).Seconds() },
+		ConnectDone: func(string, string, error) { h.info.connect = time.Since(start).Seconds() },
+		GotConn: func(info httptrace.GotConnInfo) {
+			if host, port, err := net.SplitHostPort(info.Conn.RemoteAddr().String()); err == nil {
+				h.info.primaryIP = host
+				h.info.primaryPort, _ = strconv.ParseInt(port, 10, 64)
+			}
+		},
+		GotFirstResponseByte: func() { h.info.startTransfer = time.Since(start).Seconds() },
+	}
+	httpReq = httpReq.WithContext(httptrace.WithClientTrace(context.Background(), trace))
+	return client.Do(httpReq)
+}
+
+// setHeader replaces or adds a request header; an empty value removes it,
+// as with CURLOPT_HTTPHEADER
+func setHeader(headers [][2]string, name, value string) [][2]string {
+	kept := headers[:0]
+	for _, header := range headers {
+		if !strings.EqualFold(header[0], name) {
+			kept = append(kept, header)
+		}
+	}
+	if value == "" {
+		return kept
+	}
+	return append(kept, [2]string{name, value})
+}
+
+// requestHeader returns the request header as sent, for
+// CURLINFO_HEADER_OUT
+func requestHeader(req *http.Request, headers [][2]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\r\nHost: %s\r\n", req.Method, req.URL.RequestURI(), req.URL.Host)
+	for _, header := range headers {
+		fmt.Fprintf(&b, "%s: %s\r\n", header[0], header[1])
+	}
+	if req.ContentLength > 0 {
+		fmt.Fprintf(&b, "Content-Length: %d\r\n", req.ContentLength)
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// headerBlock returns the status line and headers of a response as
+// received, ending with the empty line
+func headerBlock(resp *http.Response) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\r\n", resp.Proto, resp.Status)
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range resp.Header[name] {
+			fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+		}
+	}
+	if len(resp.TransferEncoding) > 0 {
+		fmt.Fprintf(&b, "Transfer-Encoding: %s\r\n", strings.Join(resp.TransferEncoding, ", "))
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// deliverHeader passes each header line to CURLOPT_HEADERFUNCTION
+func (h *Handle) deliverHeader(env Env, header string) error {
+	if h.headerFunction == nil {
+		return nil
+	}
+	for _, line := range strings.SplitAfter(header, "\r\n") {
+		if line == "" {
+			continue
+		}
+		if err := h.callback(env, h.headerFunction, "", line, "Failed writing header"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// callback passes data to a write or header callback, which must return
+// the number of bytes it handled. With CURLOPT_HEADER the write callback
+// receives the header before the body.
+func (h *Handle) callback(env Env, callable *types.Value, header, data, failure string) error {
+	if env.Caller == nil {
+		return nil
+	}
+	chunks := []string{data}
+	if h.includeHeader {
+		chunks = []string{header, data}
+	}
+	for _, chunk := range chunks {
+		if chunk == "" {
+			continue
+		}
+		handled, err := env.Caller.CallCallable(callable, []*types.Value{env.Handle, types.NewString(chunk)})
+		if err != nil {
+			return err
+		}
+		if handled.ToInt() != int64(len(chunk)) {
+			h.fail(CURLE_WRITE_ERROR, "%s", failure)
+			return errAborted
+		}
+	}
+	return nil
+}
+
+// failRequest records the error of a failed request with libcurl's code
+// and message
+func (h *Handle) failRequest(err error, target *url.URL, start time.Time, received int) {
+	elapsed := time.Since(start).Milliseconds()
+	port := target.Port()
+	if port == "" {
+		port = "80"
+		if target.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr):
+		h.fail(CURLE_COULDNT_RESOLVE_HOST, "Could not resolve host: %s", target.Hostname())
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		if opErr.Timeout() {
+			h.fail(CURLE_OPERATION_TIMEDOUT, "Connection timed out after %d milliseconds", elapsed)
+		} else {
+			h.fail(CURLE_COULDNT_CONNECT, "Failed to connect to %s port %s after %d ms: Couldn't connect to server", target.Hostname(), port, elapsed)
+		}
+	case errors.As(err, &unknownAuthority):
+		h.fail(CURLE_PEER_FAILED_VERIFICATION, "SSL certificate problem: unable to get local issuer certificate")
+	case errors.As(err, &hostnameErr):
+		h.fail(CURLE_PEER_FAILED_VERIFICATION, "SSL: no alternative certificate subject name matches target host name '%s'", target.Hostname())
+	case errors.As(err, &invalidCert):
+		h.fail(CURLE_PEER_FAILED_VERIFICATION, "SSL certificate problem: %s", invalidCert.Error())
+	case errors.As(err, &netErr) && netErr.Timeout():
+		h.fail(CURLE_OPERATION_TIMEDOUT, "Operation timed out after %d milliseconds with %d bytes received", elapsed, received)
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		h.fail(CURLE_GOT_NOTHING, "Empty reply from server")
+	default:
+		var recordErr tls.RecordHeaderError
+		if errors.As(err, &recordErr) {
+			h.fail(CURLE_SSL_CONNECT_ERROR, "SSL connect error")
+		} else {
+			h.fail(CURLE_RECV_ERROR, "Recv failure: %s", err)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package datetime
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Date Formatting
+// ============================================================================
+
+// Predefined formats of DateTimeInterface (DATE_ATOM, DATE_RFC2822, ...)
+const (
+	FormatATOM            = "Y-m-d\\TH:i:sP"
+	FormatCOOKIE          = "l, d-M-Y H:i:s T"
+	FormatISO8601         = "Y-m-d\\TH:i:sO"
+	FormatISO8601Expanded = "X-m-d\\TH:i:sP"
+	FormatRFC822          = "D, d M y H:i:s O"
+	FormatRFC850          = "l, d-M-y H:i:s T"
+	FormatRFC1036         = "D, d M y H:i:s O"
+	FormatRFC1123         = "D, d M Y H:i:s O"
+	FormatRFC7231         = "D, d M Y H:i:s \\G\\M\\T"
+	FormatRFC2822         = "D, d M Y H:i:s O"
+	FormatRFC3339         = "Y-m-d\\TH:i:sP"
+	FormatRFC3339Extended = "Y-m-d\\TH:i:s.vP"
+	FormatRSS             = "D, d M Y H:i:s O"
+	FormatW3C             = "Y-m-d\\TH:i:sP"
+)
+
+// Constants returns the DATE_* constants, the predefined formats also
+// declared by DateTimeInterface
+func Constants() map[string]*types.Value {
+	return map[string]*types.Value{
+		"DATE_ATOM":             types.NewString(FormatATOM),
+		"DATE_COOKIE":           types.NewString(FormatCOOKIE),
+		"DATE_ISO8601":          types.NewString(FormatISO8601),
+		"DATE_ISO8601_EXPANDED": types.NewString(FormatISO8601Expanded),
+		"DATE_RFC822":           types.NewString(FormatRFC822),
+		"DATE_RFC850":           types.NewString(FormatRFC850),
+		"DATE_RFC1036":          types.NewString(FormatRFC1036),
+		"DATE_RFC1123":          types.NewString(FormatRFC1123),
+		"DATE_RFC7231":          types.NewString(FormatRFC7231),
+		"DATE_RFC2822":          types.NewString(FormatRFC2822),
+		"DATE_RFC3339":          types.NewString(FormatRFC3339),
+		"DATE_RFC3339_EXTENDED": types.NewString(FormatRFC3339Extended),
+		"DATE_RSS":              types.NewString(FormatRSS),
+		"DATE_W3C":              types.NewString(FormatW3C),
+	}
+}
+
+// Format formats a time according to a PHP date() format string
+func Format(format string, t time.Time) string {
+	var result strings.Builder
+
+	for i := 0; i < len(format); i++ {
+		ch := format[i]
+
+		switch ch {
+		// Day
+		case 'd': // Day of month, 2 digits with leading zeros
+			writePadded(&result, t.Day(), 2)
+		case 'D': // Textual day, 3 letters
+			result.WriteString(t.Weekday().String()[:3])
+		case 'j': // Day of month without leading zeros
+			result.WriteString(strconv.Itoa(t.Day()))
+		case 'l': // Full textual day
+			result.WriteString(t.Weekday().String())
+		case 'N': // ISO-8601 day of week (1=Monday, 7=Sunday)
+			result.WriteString(strconv.Itoa(isoWeekday(t)))
+		case 'S': // English ordinal suffix of the day of month
+			result.WriteString(ordinalSuffix(t.Day()))
+		case 'w': // Day of week (0=Sunday, 6=Saturday)
+			result.WriteString(strconv.Itoa(int(t.Weekday())))
+		case 'z': // Day of year (0-365)
+			result.WriteString(strconv.Itoa(t.YearDay() - 1))
+
+		// Week
+		case 'W': // ISO-8601 week number, 2 digits
+			_, week := t.ISOWeek()
+			writePadded(&result, week, 2)
+
+		// Month
+		case 'F': // Full textual month
+			result.WriteString(t.Month().String())
+		case 'm': // Month, 2 digits with leading zeros
+			writePadded(&result, int(t.Month()), 2)
+		case 'M': // Textual month, 3 letters
+			result.WriteString(t.Month().String()[:3])
+		case 'n': // Month without leading zeros
+			result.WriteString(strconv.Itoa(int(t.Month())))
+		case 't': // Number of days in month
+			result.WriteString(strconv.Itoa(daysInMonth(t)))
+
+		// Year
+		case 'L': // Leap year (1 or 0)
+			result.WriteString(boolDigit(isLeapYear(t.Year())))
+		case 'o': // ISO-8601 week-numbering year
+			year, _ := t.ISOWeek()
+			writePadded(&result, year, 4)
+		case 'X': // Expanded year, always signed: +2024
+			if t.Year() >= 0 {
+				result.WriteByte('+')
+			}
+			writePadded(&result, t.Year(), 4)
+		case 'x': // Expanded year, signed only past 9999: +10191
+			if t.Year() >= 10000 {
+				result.WriteByte('+')
+			}
+			writePadded(&result, t.Year(), 4)
+		case 'Y': // Full year, at least 4 digits
+			writePadded(&result, t.Year(), 4)
+		case 'y': // Year, 2 digits
+			writePadded(&result, t.Year()%100, 2)
+
+		// Time
+		case 'a': // am or pm
+			if t.Hour() < 12 {
+				result.WriteString("am")
+			} else {
+				result.WriteString("pm")
+			}
+		case 'A': // AM or PM
+			if t.Hour() < 12 {
+				result.WriteString("AM")
+			} else {
+				result.WriteString("PM")
+			}
+		case 'B': // Swatch Internet time (000-999), based on UTC+1
+			utc := t.UTC()
+			seconds := (utc.Hour()*3600 + utc.Minute()*60 + utc.Second() + 3600) % 86400
+			writePadded(&result, seconds*10/864, 3)
+		case 'g': // 12-hour format without leading zeros
+			result.WriteString(strconv.Itoa(hour12(t)))
+		case 'G': // 24-hour format without leading zeros
+			result.WriteString(strconv.Itoa(t.Hour()))
+		case 'h': // 12-hour format with leading zeros
+			writePadded(&result, hour12(t), 2)
+		case 'H': // 24-hour format with leading zeros
+			writePadded(&result, t.Hour(), 2)
+		case 'i': // Minutes with leading zeros
+			writePadded(&result, t.Minute(), 2)
+		case 's': // Seconds with leading zeros
+			writePadded(&result, t.Second(), 2)
+		case 'u': // Microseconds
+			writePadded(&result, t.Nanosecond()/1000, 6)
+		case 'v': // Milliseconds
+			writePadded(&result, t.Nanosecond()/1000000, 3)
+
+		// Timezone
+		case 'e': // Timezone identifier
+			result.WriteString(t.Location().String())
+		case 'I': // Daylight saving time (1 or 0)
+			result.WriteString(boolDigit(t.IsDST()))
+		case 'O': // Difference to GMT in hours (+0200)
+			_, offset := t.Zone()
+			result.WriteString(formatOffset(offset, false))
+		case 'P': // Difference to GMT with colon (+02:00)
+			_, offset := t.Zone()
+			result.WriteString(formatOffset(offset, true))
+		case 'p': // Like P, but Z for +00:00
+			if _, offset := t.Zone(); offset == 0 {
+				result.WriteByte('Z')
+			} else {
+				result.WriteString(formatOffset(offset, true))
+			}
+		case 'T': // Timezone abbreviation
+			zone, _ := t.Zone()
+			result.WriteString(zone)
+		case 'Z': // Timezone offset in seconds
+			_, offset := t.Zone()
+			result.WriteString(strconv.Itoa(offset))
+
+		// Full date/time
+		case 'c': // ISO 8601 date
+			result.WriteString(Format(FormatATOM, t))
+		case 'r': // RFC 2822 formatted date
+			result.WriteString(Format(FormatRFC2822, t))
+		case 'U': // Unix timestamp
+			result.WriteString(strconv.FormatInt(t.Unix(), 10))
+
+		// Escape character
+		case '\\':
+			if i+1 < len(format) {
+				i++
+				result.WriteByte(format[i])
+			}
+
+		default:
+			result.WriteByte(ch)
+		}
+	}
+
+	return result.String()
+}
+
+// writePadded writes n with at least width digits
+func writePadded(result *strings.Builder, n, width int) {
+	if n < 0 {
+		result.WriteByte('-')
+		n = -n
+	}
+	s := strconv.Itoa(n)
+	for i := len(s); i < width; i++ {
+		result.WriteByte('0')
+	}
+	result.WriteString(s)
+}
+
+func boolDigit(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func hour12(t time.Time) int {
+	h := t.Hour() % 12
+	if h == 0 {
+		h = 12
+	}
+	return h
+}
+
+// isoWeekday returns the ISO-8601 day of the week (1=Monday, 7=Sunday)
+func isoWeekday(t time.Time) int {
+	day := int(t.Weekday())
+	if day == 0 {
+		day = 7
+	}
+	return day
+}
+
+// ordinalSuffix returns the English ordinal suffix of a day of the month
+func ordinalSuffix(day int) string {
+	if day >= 11 && day <= 13 {
+		return "th"
+	}
+	switch day % 10 {
+	case 1:
+		return "st"
+	case 2:
+		return "nd"
+	case 3:
+		return "rd"
+	}
+	return "th"
+}
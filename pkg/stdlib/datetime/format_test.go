@@ -0,0 +1,89 @@
+package datetime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Format Tests
+// ============================================================================
+
+func TestFormat(t *testing.T) {
+	prague, ok := LoadTimezone("Europe/Prague")
+	if !ok {
+		t.Fatal("Europe/Prague should be in the timezone database")
+	}
+	tm := time.Date(2024, 7, 4, 9, 5, 3, 123456000, prague)
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"d D j l N S w z", "04 Thu 4 Thursday 4 th 4 185"},
+		{"W F m M n t L o", "27 July 07 Jul 7 31 1 2024"},
+		{"Y y X x", "2024 24 +2024 2024"},
+		{"a A g G h H i s u v", "am AM 9 9 09 09 05 03 123456 123"},
+		{"e I O P p T Z", "Europe/Prague 1 +0200 +02:00 +02:00 CEST 7200"},
+		{"c", "2024-07-04T09:05:03+02:00"},
+		{"r", "Thu, 04 Jul 2024 09:05:03 +0200"},
+		{"U", "1720076703"},
+		{"\\Y\\m\\d Y", "Ymd 2024"},
+		{FormatRFC3339Extended, "2024-07-04T09:05:03.123+02:00"},
+	}
+
+	for _, tt := range tests {
+		if got := Format(tt.format, tm); got != tt.expected {
+			t.Errorf("Format(%q) = %q, want %q", tt.format, got, tt.expected)
+		}
+	}
+
+	if got := Format("p", tm.UTC()); got != "Z" {
+		t.Errorf("Format(p) in UTC = %q, want Z", got)
+	}
+}
+
+func TestLoadTimezone(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		zoneType int
+	}{
+		{"UTC", "UTC", ZoneTypeIdentifier},
+		{"Europe/Prague", "Europe/Prague", ZoneTypeIdentifier},
+		{"america/new_york", "America/New_York", ZoneTypeIdentifier},
+		{"+02:00", "+02:00", ZoneTypeOffset},
+		{"-0530", "-05:30", ZoneTypeOffset},
+		{"cest", "CEST", ZoneTypeAbbreviation},
+	}
+
+	for _, tt := range tests {
+		loc, ok := LoadTimezone(tt.name)
+		if !ok {
+			t.Errorf("LoadTimezone(%q) failed", tt.name)
+			continue
+		}
+		if loc.String() != tt.expected || ZoneType(loc) != tt.zoneType {
+			t.Errorf("LoadTimezone(%q) = %s (type %d), want %s (type %d)",
+				tt.name, loc, ZoneType(loc), tt.expected, tt.zoneType)
+		}
+	}
+
+	for _, name := range []string{"", "Local", "Mars/Olympus", "+1:2:3"} {
+		if _, ok := LoadTimezone(name); ok {
+			t.Errorf("LoadTimezone(%q) should fail", name)
+		}
+	}
+}
+
+func TestDefaultTimezone(t *testing.T) {
+	defer SetDefaultTimezone(DefaultTimezone())
+
+	tokyo, _ := LoadTimezone("Asia/Tokyo")
+	SetDefaultTimezone(tokyo)
+	if got := Date(types.NewString("H"), types.NewInt(0)).ToString(); got != "09" {
+		t.Errorf("date('H', 0) in Asia/Tokyo = %s, want 09", got)
+	}
+}
@@ -0,0 +1,285 @@
+package datetime
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Parsing by Format (DateTime::createFromFormat)
+// ============================================================================
+
+// FormatError reports a value that does not match a createFromFormat()
+// format
+type FormatError struct {
+	Position int
+	Message  string
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Position)
+}
+
+// formatSeparators are the characters matched by the # format character
+const formatSeparators = ";:/.,-()"
+
+// fromFormat is the state of ParseFromFormat
+type fromFormat struct {
+	value    string
+	pos      int
+	fields   parsed
+	meridian string
+	reset    bool // ! or |: fields not parsed are reset to the Unix epoch
+}
+
+// number reads between 1 and max digits, optionally signed
+func (f *fromFormat) number(max int, signed bool) (int, bool) {
+	start := f.pos
+	negative := false
+	if signed && f.pos < len(f.value) && (f.value[f.pos] == '-' || f.value[f.pos] == '+') {
+		negative = f.value[f.pos] == '-'
+		f.pos++
+	}
+	digits := f.pos
+	for f.pos < len(f.value) && f.pos-digits < max && f.value[f.pos] >= '0' && f.value[f.pos] <= '9' {
+		f.pos++
+	}
+	if f.pos == digits {
+		f.pos = start
+		return 0, false
+	}
+	n := atoi(f.value[digits:f.pos])
+	if negative {
+		n = -n
+	}
+	return n, true
+}
+
+// exactly reads a number of exactly n digits
+func (f *fromFormat) exactly(n int) (int, bool) {
+	start := f.pos
+	value, ok := f.number(n, false)
+	if ok && f.pos-start != n {
+		f.pos = start
+		return 0, false
+	}
+	return value, ok
+}
+
+// word reads a run of letters
+func (f *fromFormat) word() string {
+	start := f.pos
+	for f.pos < len(f.value) {
+		c := f.value[f.pos] | 0x20
+		if c < 'a' || c > 'z' {
+			break
+		}
+		f.pos++
+	}
+	return strings.ToLower(f.value[start:f.pos])
+}
+
+// ParseFromFormat parses value according to a DateTime::createFromFormat()
+// format. Fields the format does not parse are taken from now, or from the
+// Unix epoch after a ! or | format character; when any time field is
+// parsed the others default to zero.
+func ParseFromFormat(format, value string, now time.Time) (time.Time, error) {
+	f := &fromFormat{value: value}
+	p := &f.fields
+	p.year, p.month, p.day = unset, unset, unset
+	p.hour, p.minute, p.second, p.micro = unset, unset, unset, unset
+	dayOfYear := unset
+	ignoreTrailing := false
+
+	fail := func(message string) (time.Time, error) {
+		return time.Time{}, &FormatError{Position: f.pos, Message: message}
+	}
+
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if f.pos >= len(value) && c != '!' && c != '|' && c != '+' && c != '*' && c != ' ' {
+			return fail("Not enough data available to satisfy format")
+		}
+
+		var ok bool
+		switch c {
+		case 'd', 'j':
+			if p.day, ok = f.number(2, false); !ok {
+				return fail("A two digit day could not be found")
+			}
+		case 'S':
+			if suffix := f.word(); suffix != "st" && suffix != "nd" && suffix != "rd" && suffix != "th" {
+				return fail("The ordinal suffix could not be found")
+			}
+		case 'z':
+			if dayOfYear, ok = f.number(3, false); !ok {
+				return fail("A three digit day-of-year could not be found")
+			}
+		case 'D', 'l':
+			if _, ok = dayNames[f.word()]; !ok {
+				return fail("A textual day could not be found")
+			}
+		case 'm', 'n':
+			if p.month, ok = f.number(2, false); !ok {
+				return fail("A two digit month could not be found")
+			}
+		case 'M', 'F':
+			if p.month, ok = monthNames[f.word()]; !ok {
+				return fail("A textual month could not be found")
+			}
+		case 'y':
+			start := f.pos
+			if _, ok = f.number(2, false); !ok {
+				return fail("A two digit year could not be found")
+			}
+			p.year = year(f.value[start:f.pos])
+		case 'Y':
+			if p.year, ok = f.number(4, true); !ok {
+				return fail("A four digit year could not be found")
+			}
+		case 'a', 'A':
+			f.meridian = f.word()
+			if f.meridian != "am" && f.meridian != "pm" {
+				return fail("A meridian could not be found")
+			}
+		case 'g', 'h', 'G', 'H':
+			if p.hour, ok = f.number(2, false); !ok {
+				return fail("A two digit hour could not be found")
+			}
+		case 'i':
+			if p.minute, ok = f.exactly(2); !ok {
+				return fail("A two digit minute could not be found")
+			}
+		case 's':
+			if p.second, ok = f.exactly(2); !ok {
+				return fail("A two digit second could not be found")
+			}
+		case 'u':
+			start := f.pos
+			if _, ok = f.number(6, false); !ok {
+				return fail("A six digit microsecond could not be found")
+			}
+			p.micro = int(fractionNanos(value[start:f.pos]) / 1000)
+		case 'v':
+			start := f.pos
+			if _, ok = f.number(3, false); !ok {
+				return fail("A three digit millisecond could not be found")
+			}
+			p.micro = int(fractionNanos(value[start:f.pos]) / 1000)
+		case 'e', 'T', 'O', 'P', 'p':
+			start := f.pos
+			for f.pos < len(value) && strings.IndexByte(" ,;()", value[f.pos]) < 0 {
+				f.pos++
+			}
+			name := value[start:f.pos]
+			if name == "Z" || name == "z" {
+				name = "UTC"
+			}
+			loc, found := LoadTimezone(name)
+			if !found {
+				f.pos = start
+				return fail("The timezone could not be found in the database")
+			}
+			p.zone = loc
+		case 'U':
+			seconds, found := f.number(20, true)
+			if !found {
+				return fail("A unix timestamp could not be found")
+			}
+			ts := time.Unix(int64(seconds), 0).UTC()
+			p.timestamp = &ts
+		case ' ':
+			for f.pos < len(value) && (value[f.pos] == ' ' || value[f.pos] == '\t') {
+				f.pos++
+			}
+		case '#':
+			if strings.IndexByte(formatSeparators, value[f.pos]) < 0 {
+				return fail("The separation symbol ([;:/.,-]) could not be found")
+			}
+			f.pos++
+		case ';', ':', '/', '.', ',', '-', '(', ')':
+			if value[f.pos] != c {
+				return fail("The separation symbol could not be found")
+			}
+			f.pos++
+		case '?':
+			f.pos++
+		case '*':
+			for f.pos < len(value) && strings.IndexByte(formatSeparators+" 0123456789", value[f.pos]) < 0 {
+				f.pos++
+			}
+		case '!', '|':
+			f.reset = true
+		case '+':
+			ignoreTrailing = true
+		case '\\':
+			if i+1 < len(format) {
+				i++
+			}
+			if value[f.pos] != format[i] {
+				return fail("The escaped character could not be found")
+			}
+			f.pos++
+		default:
+			if value[f.pos] != c {
+				return fail("The format separator does not match")
+			}
+			f.pos++
+		}
+	}
+	if f.pos < len(value) && !ignoreTrailing {
+		return fail("Trailing data")
+	}
+
+	if f.meridian != "" && p.hour != unset {
+		hour, err := meridian(p.hour, f.meridian[:1])
+		if err != nil {
+			return fail("Hour cannot be higher than 12")
+		}
+		p.hour = hour
+	}
+	return f.resolve(now, dayOfYear), nil
+}
+
+// resolve fills in the fields the format did not parse
+func (f *fromFormat) resolve(now time.Time, dayOfYear int) time.Time {
+	p := &f.fields
+	if p.timestamp != nil {
+		t := *p.timestamp
+		if p.zone != nil {
+			t = t.In(p.zone)
+		}
+		return t
+	}
+
+	loc := now.Location()
+	if p.zone != nil {
+		loc = p.zone
+	}
+	base := now.In(loc)
+	if f.reset {
+		base = time.Date(1970, 1, 1, 0, 0, 0, 0, loc)
+	}
+
+	y, m, d := base.Date()
+	h, i, s := base.Clock()
+	micro := base.Nanosecond() / 1000
+	if p.hour != unset || p.minute != unset || p.second != unset || p.micro != unset {
+		h, i, s, micro = 0, 0, 0, 0
+	}
+	pick := func(field, fallback int) int {
+		if field == unset {
+			return fallback
+		}
+		return field
+	}
+
+	year := pick(p.year, y)
+	month, day := pick(p.month, int(m)), pick(p.day, d)
+	if dayOfYear != unset {
+		month, day = 1, dayOfYear+1
+	}
+	return time.Date(year, time.Month(month), day, pick(p.hour, h), pick(p.minute, i),
+		pick(p.second, s), pick(p.micro, micro)*1000, loc)
+}
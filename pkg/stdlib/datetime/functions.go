@@ -0,0 +1,263 @@
+package datetime
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Time Functions
+// ============================================================================
+
+// Time returns current Unix timestamp
+// time(): int
+func Time() *types.Value {
+	return types.NewInt(time.Now().Unix())
+}
+
+// Microtime returns current Unix timestamp with microseconds
+// microtime(bool $as_float = false): string|float
+func Microtime(args ...*types.Value) *types.Value {
+	asFloat := false
+	if len(args) > 0 {
+		asFloat = args[0].ToBool()
+	}
+
+	now := time.Now()
+	sec := now.Unix()
+	usec := now.UnixMicro() % 1000000
+
+	if asFloat {
+		return types.NewFloat(float64(sec) + float64(usec)/1000000.0)
+	}
+
+	return types.NewString(fmt.Sprintf("0.%06d %d", usec, sec))
+}
+
+// ============================================================================
+// Date Formatting Functions
+// ============================================================================
+
+// Date formats a Unix timestamp
+// date(string $format, int $timestamp = null): string
+func Date(format *types.Value, args ...*types.Value) *types.Value {
+	var t time.Time
+	if len(args) > 0 && !args[0].IsNull() {
+		t = time.Unix(args[0].ToInt(), 0)
+	} else {
+		t = time.Now()
+	}
+
+	return types.NewString(Format(format.ToString(), t.In(DefaultTimezone())))
+}
+
+// Gmdate formats a GMT/UTC date/time
+// gmdate(string $format, int $timestamp = null): string
+func Gmdate(format *types.Value, args ...*types.Value) *types.Value {
+	var t time.Time
+	if len(args) > 0 && !args[0].IsNull() {
+		t = time.Unix(args[0].ToInt(), 0).UTC()
+	} else {
+		t = time.Now().UTC()
+	}
+
+	return types.NewString(Format(format.ToString(), t))
+}
+
+// Helper functions
+func daysInMonth(t time.Time) int {
+	// Get first day of next month, then go back one day
+	firstOfMonth := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	lastOfMonth := firstOfMonth.AddDate(0, 1, -1)
+	return lastOfMonth.Day()
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// ============================================================================
+// Mktime and Strtotime
+// ============================================================================
+
+// Mktime returns Unix timestamp for a date
+// mktime(int $hour, int $minute = 0, int $second = 0, int $month = 1, int $day = 1, int $year = 0): int|false
+func Mktime(args ...*types.Value) *types.Value {
+	return mktime(DefaultTimezone(), args)
+}
+
+// Gmmktime returns Unix timestamp for a GMT date
+// gmmktime(int $hour, int $minute = 0, int $second = 0, int $month = 1, int $day = 1, int $year = 0): int|false
+func Gmmktime(args ...*types.Value) *types.Value {
+	return mktime(time.UTC, args)
+}
+
+// mktime computes the timestamp of a date in loc; missing arguments are
+// taken from the current time
+func mktime(loc *time.Location, args []*types.Value) *types.Value {
+	now := time.Now().In(loc)
+
+	hour := now.Hour()
+	minute := now.Minute()
+	second := now.Second()
+	month := int(now.Month())
+	day := now.Day()
+	year := now.Year()
+
+	if len(args) > 0 {
+		hour = int(args[0].ToInt())
+	}
+	if len(args) > 1 {
+		minute = int(args[1].ToInt())
+	}
+	if len(args) > 2 {
+		second = int(args[2].ToInt())
+	}
+	if len(args) > 3 {
+		month = int(args[3].ToInt())
+	}
+	if len(args) > 4 {
+		day = int(args[4].ToInt())
+	}
+	if len(args) > 5 {
+		year = int(args[5].ToInt())
+	}
+
+	// Handle 2-digit years
+	if year >= 0 && year < 70 {
+		year += 2000
+	} else if year >= 70 && year < 100 {
+		year += 1900
+	}
+
+	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, loc)
+	return types.NewInt(t.Unix())
+}
+
+// Strtotime parses an English textual datetime into a Unix timestamp
+// strtotime(string $datetime, int $baseTimestamp = null): int|false
+func Strtotime(datetime *types.Value, args ...*types.Value) *types.Value {
+	now := time.Now()
+	if len(args) > 0 && !args[0].IsNull() {
+		now = time.Unix(args[0].ToInt(), 0)
+	}
+
+	t, err := Parse(datetime.ToString(), now.In(DefaultTimezone()))
+	if err != nil {
+		return types.NewBool(false)
+	}
+	return types.NewInt(t.Unix())
+}
+
+// ============================================================================
+// Getdate
+// ============================================================================
+
+// Getdate gets date/time information
+// getdate(int $timestamp = null): array
+func Getdate(args ...*types.Value) *types.Value {
+	t := time.Now()
+	if len(args) > 0 && !args[0].IsNull() {
+		t = time.Unix(args[0].ToInt(), 0)
+	}
+	t = t.In(DefaultTimezone())
+
+	arr := types.NewEmptyArray()
+	arr.Set(types.NewString("seconds"), types.NewInt(int64(t.Second())))
+	arr.Set(types.NewString("minutes"), types.NewInt(int64(t.Minute())))
+	arr.Set(types.NewString("hours"), types.NewInt(int64(t.Hour())))
+	arr.Set(types.NewString("mday"), types.NewInt(int64(t.Day())))
+	arr.Set(types.NewString("wday"), types.NewInt(int64(t.Weekday())))
+	arr.Set(types.NewString("mon"), types.NewInt(int64(t.Month())))
+	arr.Set(types.NewString("year"), types.NewInt(int64(t.Year())))
+	arr.Set(types.NewString("yday"), types.NewInt(int64(t.YearDay()-1)))
+	arr.Set(types.NewString("weekday"), types.NewString(t.Weekday().String()))
+	arr.Set(types.NewString("month"), types.NewString(t.Month().String()))
+	arr.Set(types.NewString("0"), types.NewInt(t.Unix()))
+
+	return types.NewArray(arr)
+}
+
+// ============================================================================
+// Localtime
+// ============================================================================
+
+// Localtime gets the local time
+// localtime(int $timestamp = null, bool $associative = false): array
+func Localtime(args ...*types.Value) *types.Value {
+	t := time.Now()
+	if len(args) > 0 && !args[0].IsNull() {
+		t = time.Unix(args[0].ToInt(), 0)
+	}
+	t = t.In(DefaultTimezone())
+
+	associative := false
+	if len(args) > 1 {
+		associative = args[1].ToBool()
+	}
+
+	var isDST int64
+	if t.IsDST() {
+		isDST = 1
+	}
+
+	arr := types.NewEmptyArray()
+
+	if associative {
+		arr.Set(types.NewString("tm_sec"), types.NewInt(int64(t.Second())))
+		arr.Set(types.NewString("tm_min"), types.NewInt(int64(t.Minute())))
+		arr.Set(types.NewString("tm_hour"), types.NewInt(int64(t.Hour())))
+		arr.Set(types.NewString("tm_mday"), types.NewInt(int64(t.Day())))
+		arr.Set(types.NewString("tm_mon"), types.NewInt(int64(t.Month()-1))) // 0-11
+		arr.Set(types.NewString("tm_year"), types.NewInt(int64(t.Year()-1900)))
+		arr.Set(types.NewString("tm_wday"), types.NewInt(int64(t.Weekday())))
+		arr.Set(types.NewString("tm_yday"), types.NewInt(int64(t.YearDay()-1)))
+		arr.Set(types.NewString("tm_isdst"), types.NewInt(isDST))
+	} else {
+		arr.Append(types.NewInt(int64(t.Second())))
+		arr.Append(types.NewInt(int64(t.Minute())))
+		arr.Append(types.NewInt(int64(t.Hour())))
+		arr.Append(types.NewInt(int64(t.Day())))
+		arr.Append(types.NewInt(int64(t.Month() - 1))) // 0-11
+		arr.Append(types.NewInt(int64(t.Year() - 1900)))
+		arr.Append(types.NewInt(int64(t.Weekday())))
+		arr.Append(types.NewInt(int64(t.YearDay() - 1)))
+		arr.Append(types.NewInt(isDST))
+	}
+
+	return types.NewArray(arr)
+}
+
+// ============================================================================
+// Checkdate
+// ============================================================================
+
+// Checkdate validates a Gregorian date
+// checkdate(int $month, int $day, int $year): bool
+func Checkdate(month *types.Value, day *types.Value, year *types.Value) *types.Value {
+	m := int(month.ToInt())
+	d := int(day.ToInt())
+	y := int(year.ToInt())
+
+	// Month must be 1-12
+	if m < 1 || m > 12 {
+		return types.NewBool(false)
+	}
+
+	// Year must be 1-32767
+	if y < 1 || y > 32767 {
+		return types.NewBool(false)
+	}
+
+	// Check day against month
+	t := time.Date(y, time.Month(m), 1, 0, 0, 0, 0, time.UTC)
+	maxDay := daysInMonth(t)
+
+	if d < 1 || d > maxDay {
+		return types.NewBool(false)
+	}
+
+	return types.NewBool(true)
+}
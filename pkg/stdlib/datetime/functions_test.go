@@ -1,4 +1,4 @@
-package date
+package datetime
 
 import (
 	"strings"
@@ -59,7 +59,7 @@ func TestMicrotimeFloat(t *testing.T) {
 
 func TestDateBasicFormats(t *testing.T) {
 	// Use a known timestamp in local time: 2024-03-15 14:30:45
-	timestamp := time.Date(2024, 3, 15, 14, 30, 45, 0, time.Local).Unix()
+	timestamp := time.Date(2024, 3, 15, 14, 30, 45, 0, DefaultTimezone()).Unix()
 
 	tests := []struct {
 		format   string
@@ -132,7 +132,7 @@ func TestDateDayFormats(t *testing.T) {
 
 func TestDate12HourFormats(t *testing.T) {
 	// 2PM (14:00) in local time
-	timestamp := time.Date(2024, 1, 1, 14, 0, 0, 0, time.Local).Unix()
+	timestamp := time.Date(2024, 1, 1, 14, 0, 0, 0, DefaultTimezone()).Unix()
 
 	tests := []struct {
 		format   string
@@ -203,7 +203,7 @@ func TestMktime(t *testing.T) {
 		types.NewInt(2024), // year
 	)
 
-	expected := time.Date(2024, 3, 15, 14, 30, 45, 0, time.Local).Unix()
+	expected := time.Date(2024, 3, 15, 14, 30, 45, 0, DefaultTimezone()).Unix()
 	if result.ToInt() != expected {
 		t.Errorf("mktime(14,30,45,3,15,2024) = %v, want %v", result.ToInt(), expected)
 	}
@@ -220,7 +220,7 @@ func TestMktimeTwoDigitYear(t *testing.T) {
 		types.NewInt(24),
 	)
 
-	expected := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local).Unix()
+	expected := time.Date(2024, 1, 1, 0, 0, 0, 0, DefaultTimezone()).Unix()
 	if result.ToInt() != expected {
 		t.Errorf("mktime with year 24 should give 2024")
 	}
@@ -235,7 +235,7 @@ func TestMktimeTwoDigitYear(t *testing.T) {
 		types.NewInt(95),
 	)
 
-	expected = time.Date(1995, 1, 1, 0, 0, 0, 0, time.Local).Unix()
+	expected = time.Date(1995, 1, 1, 0, 0, 0, 0, DefaultTimezone()).Unix()
 	if result.ToInt() != expected {
 		t.Errorf("mktime with year 95 should give 1995")
 	}
@@ -320,7 +320,7 @@ func TestStrtotimeInvalid(t *testing.T) {
 // ============================================================================
 
 func TestGetdate(t *testing.T) {
-	timestamp := time.Date(2024, 3, 15, 14, 30, 45, 0, time.Local).Unix()
+	timestamp := time.Date(2024, 3, 15, 14, 30, 45, 0, DefaultTimezone()).Unix()
 	result := Getdate(types.NewInt(timestamp))
 
 	if result.Type() != types.TypeArray {
@@ -366,7 +366,7 @@ func TestGetdate(t *testing.T) {
 // ============================================================================
 
 func TestLocaltimeIndexed(t *testing.T) {
-	timestamp := time.Date(2024, 3, 15, 14, 30, 45, 0, time.Local).Unix()
+	timestamp := time.Date(2024, 3, 15, 14, 30, 45, 0, DefaultTimezone()).Unix()
 	result := Localtime(types.NewInt(timestamp))
 
 	if result.Type() != types.TypeArray {
@@ -388,7 +388,7 @@ func TestLocaltimeIndexed(t *testing.T) {
 }
 
 func TestLocaltimeAssociative(t *testing.T) {
-	timestamp := time.Date(2024, 3, 15, 14, 30, 45, 0, time.Local).Unix()
+	timestamp := time.Date(2024, 3, 15, 14, 30, 45, 0, DefaultTimezone()).Unix()
 	result := Localtime(types.NewInt(timestamp), types.NewBool(true))
 
 	if result.Type() != types.TypeArray {
@@ -0,0 +1,286 @@
+package datetime
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Date Intervals
+// ============================================================================
+
+// Interval is the difference between two times, kept per field as PHP's
+// DateInterval does
+type Interval struct {
+	Years, Months, Days     int
+	Hours, Minutes, Seconds int
+	Microseconds            int
+	Invert                  bool // the interval is negative
+	TotalDays               int  // whole days between the times, -1 if unknown
+}
+
+// IntervalError reports an interval specification that could not be parsed
+type IntervalError struct {
+	Spec string
+}
+
+func (e *IntervalError) Error() string {
+	return fmt.Sprintf("Unknown or bad format (%s)", e.Spec)
+}
+
+// ParseInterval parses an ISO 8601 duration such as P1Y2M10DT2H30M or P2W
+func ParseInterval(spec string) (Interval, error) {
+	iv := Interval{TotalDays: -1}
+	bad := &IntervalError{Spec: spec}
+	if len(spec) < 2 || spec[0] != 'P' {
+		return iv, bad
+	}
+
+	inTime, fields := false, 0
+	for i := 1; i < len(spec); {
+		if spec[i] == 'T' {
+			if inTime || i+1 == len(spec) {
+				return iv, bad
+			}
+			inTime = true
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(spec) && spec[i] >= '0' && spec[i] <= '9' {
+			i++
+		}
+		if i == start || i == len(spec) {
+			return iv, bad
+		}
+		n, err := strconv.Atoi(spec[start:i])
+		if err != nil {
+			return iv, bad
+		}
+
+		switch designator := spec[i]; {
+		case !inTime && designator == 'Y':
+			iv.Years = n
+		case !inTime && designator == 'M':
+			iv.Months = n
+		case !inTime && designator == 'W':
+			iv.Days += 7 * n
+		case !inTime && designator == 'D':
+			iv.Days += n
+		case inTime && designator == 'H':
+			iv.Hours = n
+		case inTime && designator == 'M':
+			iv.Minutes = n
+		case inTime && designator == 'S':
+			iv.Seconds = n
+		default:
+			return iv, bad
+		}
+		i++
+		fields++
+	}
+	if fields == 0 {
+		return iv, bad
+	}
+	return iv, nil
+}
+
+// ParseRelativeInterval returns the relative parts of a date/time string
+// ("1 day + 12 hours", "last year") as an interval, for
+// DateInterval::createFromDateString()
+func ParseRelativeInterval(text string) (Interval, error) {
+	var p parsed
+	if err := p.scan(text); err != nil {
+		return Interval{}, err
+	}
+	return Interval{
+		Years: p.years, Months: p.months, Days: p.days,
+		Hours: p.hours, Minutes: p.minutes, Seconds: p.seconds,
+		Microseconds: p.micros, TotalDays: -1,
+	}, nil
+}
+
+// Diff returns the interval from a to b. Times in the same timezone are
+// compared by their wall clock, others in UTC.
+func Diff(a, b time.Time) Interval {
+	if a.Location().String() == b.Location().String() {
+		b = b.In(a.Location())
+	} else {
+		a, b = a.UTC(), b.UTC()
+	}
+	iv := Interval{}
+	if b.Before(a) {
+		a, b = b, a
+		iv.Invert = true
+	}
+
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	ah, ai, as := a.Clock()
+	bh, bi, bs := b.Clock()
+
+	micro := b.Nanosecond()/1000 - a.Nanosecond()/1000
+	second := bs - as
+	minute := bi - ai
+	hour := bh - ah
+	day := bd - ad
+	month := int(bm) - int(am)
+	year := by - ay
+
+	if micro < 0 {
+		micro += 1000000
+		second--
+	}
+	if second < 0 {
+		second += 60
+		minute--
+	}
+	if minute < 0 {
+		minute += 60
+		hour--
+	}
+	if hour < 0 {
+		hour += 24
+		day--
+	}
+	// Borrowed days are counted the way PHP does: from the later time's
+	// month onwards, or from the earlier one's backwards when inverted
+	baseYear, baseMonth, step := by, bm, time.Month(1)
+	if iv.Invert {
+		baseYear, baseMonth, step = ay, am, -1
+	}
+	for borrow := time.Month(0); day < 0; borrow += step {
+		day += daysInMonth(time.Date(baseYear, baseMonth+borrow, 1, 0, 0, 0, 0, time.UTC))
+		month--
+	}
+	if month < 0 {
+		month += 12
+		year--
+	}
+
+	iv.Years, iv.Months, iv.Days = year, month, day
+	iv.Hours, iv.Minutes, iv.Seconds, iv.Microseconds = hour, minute, second, micro
+	iv.TotalDays = civilDays(b) - civilDays(a)
+	if clockNanos(b) < clockNanos(a) {
+		iv.TotalDays--
+	}
+	return iv
+}
+
+// civilDays returns the number of days from 1970-01-01 to t's date
+func civilDays(t time.Time) int {
+	y, m, d := t.Date()
+	return int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// clockNanos returns the time of day of t in nanoseconds
+func clockNanos(t time.Time) int {
+	h, m, s := t.Clock()
+	return ((h*60+m)*60+s)*1e9 + t.Nanosecond()
+}
+
+// Add adds the interval to t (subtracts it when sign is -1). As in PHP,
+// the years, months and days are applied to the wall clock, so adding a
+// month to January 31st overflows into March, and the time fields to the
+// elapsed time, so adding an hour crosses a DST transition.
+func (iv Interval) Add(t time.Time, sign int) time.Time {
+	if iv.Invert {
+		sign = -sign
+	}
+	y, m, d := t.Date()
+	h, i, s := t.Clock()
+	if iv.Years != 0 || iv.Months != 0 || iv.Days != 0 {
+		t = wallTime(y+sign*iv.Years, int(m)+sign*iv.Months, d+sign*iv.Days, h, i, s, t.Nanosecond(), t.Location())
+	}
+	return t.Add(time.Duration(sign) * elapsed(iv.Hours, iv.Minutes, iv.Seconds, iv.Microseconds))
+}
+
+// wallTime returns the time showing the given wall clock in loc, with the
+// fields normalized as by time.Date. A wall clock skipped by a DST
+// transition is moved forward by the gap: 02:30 on the day clocks jump
+// from 02:00 to 03:00 is 03:30.
+func wallTime(year, month, day, hour, minute, second, nanos int, loc *time.Location) time.Time {
+	t := time.Date(year, time.Month(month), day, hour, minute, second, nanos, loc)
+	wall := time.Date(year, time.Month(month), day, hour, minute, second, nanos, time.UTC)
+	if civilDays(t) == civilDays(wall) && clockNanos(t) == clockNanos(wall) {
+		return t
+	}
+	// Read the wall clock with the offset in effect before the gap
+	_, offset := wall.Add(-24 * time.Hour).In(loc).Zone()
+	return wall.Add(-time.Duration(offset) * time.Second).In(loc)
+}
+
+// elapsed returns the duration of the time fields of an interval
+func elapsed(hours, minutes, seconds, micros int) time.Duration {
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second + time.Duration(micros)*time.Microsecond
+}
+
+// Format formats the interval according to a DateInterval::format() format:
+// %y, %m, %d, %h, %i, %s and %f print the fields (uppercase with leading
+// zeros), %a the total days, %R the sign and %r the sign when negative
+func (iv Interval) Format(format string) string {
+	var result strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			result.WriteByte(format[i])
+			continue
+		}
+		i++
+		switch format[i] {
+		case 'Y':
+			writePadded(&result, iv.Years, 4)
+		case 'y':
+			result.WriteString(strconv.Itoa(iv.Years))
+		case 'M':
+			writePadded(&result, iv.Months, 2)
+		case 'm':
+			result.WriteString(strconv.Itoa(iv.Months))
+		case 'D':
+			writePadded(&result, iv.Days, 2)
+		case 'd':
+			result.WriteString(strconv.Itoa(iv.Days))
+		case 'H':
+			writePadded(&result, iv.Hours, 2)
+		case 'h':
+			result.WriteString(strconv.Itoa(iv.Hours))
+		case 'I':
+			writePadded(&result, iv.Minutes, 2)
+		case 'i':
+			result.WriteString(strconv.Itoa(iv.Minutes))
+		case 'S':
+			writePadded(&result, iv.Seconds, 2)
+		case 's':
+			result.WriteString(strconv.Itoa(iv.Seconds))
+		case 'F':
+			writePadded(&result, iv.Microseconds, 6)
+		case 'f':
+			result.WriteString(strconv.Itoa(iv.Microseconds))
+		case 'a':
+			if iv.TotalDays < 0 {
+				result.WriteString("(unknown)")
+			} else {
+				result.WriteString(strconv.Itoa(iv.TotalDays))
+			}
+		case 'R':
+			if iv.Invert {
+				result.WriteByte('-')
+			} else {
+				result.WriteByte('+')
+			}
+		case 'r':
+			if iv.Invert {
+				result.WriteByte('-')
+			}
+		case '%':
+			result.WriteByte('%')
+		default:
+			result.WriteByte('%')
+			result.WriteByte(format[i])
+		}
+	}
+	return result.String()
+}
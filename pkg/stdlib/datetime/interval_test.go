@@ -0,0 +1,136 @@
+package datetime
+
+import (
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// Interval Tests
+// ============================================================================
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected string
+	}{
+		{"P1Y2M3DT4H5M6S", "1-2-3 4:5:6"},
+		{"P2W", "0-0-14 0:0:0"},
+		{"PT36H", "0-0-0 36:0:0"},
+		{"P1M", "0-1-0 0:0:0"},
+		{"PT1M", "0-0-0 0:1:0"},
+	}
+
+	for _, tt := range tests {
+		iv, err := ParseInterval(tt.spec)
+		if err != nil {
+			t.Errorf("ParseInterval(%q) error: %v", tt.spec, err)
+			continue
+		}
+		if s := iv.Format("%y-%m-%d %h:%i:%s"); s != tt.expected {
+			t.Errorf("ParseInterval(%q) = %s, want %s", tt.spec, s, tt.expected)
+		}
+	}
+
+	for _, spec := range []string{"", "P", "PT", "1D", "P1X", "PT1D", "P1H", "PD"} {
+		if _, err := ParseInterval(spec); err == nil {
+			t.Errorf("ParseInterval(%q) should fail", spec)
+		} else if err.Error() != "Unknown or bad format ("+spec+")" {
+			t.Errorf("ParseInterval(%q) error = %q", spec, err.Error())
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02 15:04:05", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		a, b     string
+		expected string
+	}{
+		{"2024-01-01 00:00:00", "2024-03-15 12:30:00", "+0-2-14 12:30:0 days=74"},
+		{"2024-03-15 12:30:00", "2024-01-01 00:00:00", "-0-2-14 12:30:0 days=74"},
+		{"2024-01-31 00:00:00", "2024-03-01 00:00:00", "+0-1-1 0:0:0 days=30"},
+		{"2023-12-31 23:00:00", "2024-01-01 01:00:00", "+0-0-0 2:0:0 days=0"},
+		{"2020-02-29 00:00:00", "2024-02-28 00:00:00", "+3-11-28 0:0:0 days=1460"},
+	}
+
+	for _, tt := range tests {
+		iv := Diff(date(tt.a), date(tt.b))
+		if s := iv.Format("%R%y-%m-%d %h:%i:%s days=%a"); s != tt.expected {
+			t.Errorf("Diff(%s, %s) = %s, want %s", tt.a, tt.b, s, tt.expected)
+		}
+	}
+
+	// Different timezones are compared in UTC
+	prague, _ := LoadTimezone("Europe/Prague")
+	a := time.Date(2024, 1, 1, 12, 0, 0, 0, prague)
+	b := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if s := Diff(a, b).Format("%R%h"); s != "+1" {
+		t.Errorf("Diff across timezones = %s, want +1", s)
+	}
+}
+
+func TestIntervalAdd(t *testing.T) {
+	base := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+	month, _ := ParseInterval("P1M")
+	if got := month.Add(base, 1); !got.Equal(time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("2024-01-31 + P1M = %v", got)
+	}
+	if got := month.Add(base, -1); !got.Equal(time.Date(2023, 12, 31, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("2024-01-31 - P1M = %v", got)
+	}
+
+	month.Invert = true
+	if got := month.Add(base, 1); !got.Equal(time.Date(2023, 12, 31, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("2024-01-31 + inverted P1M = %v", got)
+	}
+
+	// Hours are elapsed time and days wall clock time across DST gaps
+	newYork, _ := time.LoadLocation("America/New_York")
+	gapTests := []struct {
+		base, spec, expected string
+	}{
+		{"2021-03-14T01:30:00-05:00", "PT1H", "2021-03-14T03:30:00-04:00"},
+		{"2021-03-14T03:30:00-04:00", "-PT1H", "2021-03-14T01:30:00-05:00"},
+		{"2021-03-13T02:30:00-05:00", "P1D", "2021-03-14T03:30:00-04:00"},
+		{"2021-03-13T12:00:00-05:00", "P1DT1H", "2021-03-14T13:00:00-04:00"},
+	}
+	for _, tt := range gapTests {
+		start, _ := time.Parse(time.RFC3339, tt.base)
+		spec, sign := tt.spec, 1
+		if spec[0] == '-' {
+			spec, sign = spec[1:], -1
+		}
+		iv, _ := ParseInterval(spec)
+		if got := iv.Add(start.In(newYork), sign).Format(time.RFC3339); got != tt.expected {
+			t.Errorf("%s %s = %s, want %s", tt.base, tt.spec, got, tt.expected)
+		}
+	}
+}
+
+func TestIntervalFormat(t *testing.T) {
+	iv := Interval{Years: 1, Months: 2, Days: 3, Hours: 4, Minutes: 5, Seconds: 6, Microseconds: 7, TotalDays: -1}
+	if s := iv.Format("%Y %M %D %H %I %S %F %a %r %% %q"); s != "0001 02 03 04 05 06 000007 (unknown)  % %q" {
+		t.Errorf("Format = %q", s)
+	}
+}
+
+func TestParseRelativeInterval(t *testing.T) {
+	iv, err := ParseRelativeInterval("1 day + 12 hours")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := iv.Format("%d %h"); s != "1 12" {
+		t.Errorf("ParseRelativeInterval = %s, want 1 12", s)
+	}
+	if _, err := ParseRelativeInterval("not an interval"); err == nil {
+		t.Error("ParseRelativeInterval should fail on garbage")
+	}
+}
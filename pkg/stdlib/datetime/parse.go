@@ -0,0 +1,577 @@
+package datetime
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Date/Time String Parser
+// ============================================================================
+
+// ParseError reports a date/time string that could not be parsed
+type ParseError struct {
+	Text     string
+	Position int
+	Message  string
+}
+
+func (e *ParseError) Error() string {
+	if e.Position >= len(e.Text) {
+		return fmt.Sprintf("Failed to parse time string (%s) at position %d: %s", e.Text, e.Position, e.Message)
+	}
+	return fmt.Sprintf("Failed to parse time string (%s) at position %d (%c): %s", e.Text, e.Position, e.Text[e.Position], e.Message)
+}
+
+// unset marks a date or time field the string does not specify
+const unset = -1 << 31
+
+// Weekday behaviors of relative weekday statements
+const (
+	weekdayThis = iota // "monday", "this monday": today or later
+	weekdayNext        // "next monday": after today
+	weekdayLast        // "last monday": before today
+)
+
+// parsed holds the absolute and relative parts of a date/time string
+type parsed struct {
+	year, month, day             int
+	hour, minute, second, micro  int
+	haveDate, haveTime, haveZone bool
+	resetTime                    bool // today, midnight, weekday names: 00:00 unless a time is given
+	timestamp                    *time.Time
+	zone                         *time.Location
+
+	// Relative offsets
+	years, months, days             int
+	hours, minutes, seconds, micros int
+	weekdays                        int // business days
+	weekday                         time.Weekday
+	haveWeekday                     bool
+	weekdayBehavior, weekdayCount   int
+	weekdayInWeek                   bool // "monday next week": the weekday of the target week
+	firstLastDayOf                  int  // 1 for "first day of", 2 for "last day of"
+	nthWeekdayOf                    int  // "second monday of": 2, "last monday of": -1
+}
+
+var (
+	relativeNumbers = map[string]int{
+		"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5, "sixth": 6,
+		"seventh": 7, "eighth": 8, "ninth": 9, "tenth": 10, "eleventh": 11, "twelfth": 12,
+		"next": 1, "last": -1, "previous": -1, "this": 0,
+	}
+	dayNames = map[string]time.Weekday{
+		"sunday": time.Sunday, "sun": time.Sunday,
+		"monday": time.Monday, "mon": time.Monday,
+		"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+		"wednesday": time.Wednesday, "wed": time.Wednesday,
+		"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+		"friday": time.Friday, "fri": time.Friday,
+		"saturday": time.Saturday, "sat": time.Saturday,
+	}
+	monthNames = map[string]int{
+		"january": 1, "jan": 1, "february": 2, "feb": 2, "march": 3, "mar": 3,
+		"april": 4, "apr": 4, "may": 5, "june": 6, "jun": 6, "july": 7, "jul": 7,
+		"august": 8, "aug": 8, "september": 9, "sept": 9, "sep": 9, "october": 10, "oct": 10,
+		"november": 11, "nov": 11, "december": 12, "dec": 12,
+	}
+)
+
+const (
+	reltextPattern = `first|second|third|fourth|fifth|sixth|seventh|eighth|ninth|tenth|eleventh|twelfth|next|last|previous|this`
+	dayPattern     = `monday|mon|tuesday|tues|tue|wednesday|wed|thursday|thurs|thur|thu|friday|fri|saturday|sat|sunday|sun`
+	monthPattern   = `january|jan|february|feb|march|mar|april|apr|may|june|jun|july|jul|august|aug|september|sept|sep|october|oct|november|nov|december|dec`
+	unitPattern    = `(?:millisecond|msec|microsecond|usec|µsec|µs|second|sec|minute|min|hour|day|fortnight|forthnight|month|year|weekday|week)s?|` + dayPattern
+	suffixPattern  = `(?:st|nd|rd|th)?`
+)
+
+// rule is one format of the scanner: a pattern anchored at the current
+// position and the handler storing what it matched
+type rule struct {
+	pattern *regexp.Regexp
+	apply   func(p *parsed, m []string, original string) error
+}
+
+func newRule(pattern string, apply func(p *parsed, m []string, original string) error) rule {
+	return rule{regexp.MustCompile(`^(?:` + pattern + `)`), apply}
+}
+
+// rules are tried in order at every position; more specific formats come
+// first. Alternatives list longer words first, as a rule fails when its
+// match stops inside a word or number.
+var rules []rule
+
+func init() {
+	rules = []rule{
+		newRule(`@(-?\d+)(?:\.(\d{1,6}))?`, func(p *parsed, m []string, _ string) error {
+			seconds, _ := strconv.ParseInt(m[1], 10, 64)
+			fraction := time.Duration(fractionNanos(m[2]))
+			if m[1][0] == '-' {
+				fraction = -fraction
+			}
+			// A timestamp is in UTC, as the offset +00:00
+			t := time.Unix(seconds, 0).Add(fraction).In(FixedOffset(0))
+			p.timestamp = &t
+			return nil
+		}),
+		newRule(`yesterday|today|now|midnight|noon|tomorrow`, func(p *parsed, m []string, _ string) error {
+			switch m[0] {
+			case "yesterday":
+				p.days--
+				p.resetTime = true
+			case "tomorrow":
+				p.days++
+				p.resetTime = true
+			case "today", "midnight":
+				p.resetTime = true
+			case "noon":
+				return p.setTime(12, 0, 0, 0)
+			}
+			return nil
+		}),
+		newRule(`(first|last) day of`, func(p *parsed, m []string, _ string) error {
+			p.firstLastDayOf = 1
+			if m[1] == "last" {
+				p.firstLastDayOf = 2
+			}
+			return nil
+		}),
+		newRule(`(`+reltextPattern+`)\s+(`+dayPattern+`)\s+of`, func(p *parsed, m []string, _ string) error {
+			p.nthWeekdayOf = relativeNumbers[m[1]]
+			if p.nthWeekdayOf <= 0 {
+				p.nthWeekdayOf = -1
+			}
+			p.weekday = dayNames[m[2]]
+			p.resetTime = true
+			return nil
+		}),
+
+		// Dates
+		newRule(`([+-]?\d{4})-(\d{1,2})-(\d{1,2})`, func(p *parsed, m []string, _ string) error {
+			return p.setDate(atoi(m[1]), atoi(m[2]), atoi(m[3]))
+		}),
+		newRule(`(\d{4})/(\d{1,2})/(\d{1,2})`, func(p *parsed, m []string, _ string) error {
+			return p.setDate(atoi(m[1]), atoi(m[2]), atoi(m[3]))
+		}),
+		newRule(`(\d{4})(\d{2})(\d{2})`, func(p *parsed, m []string, _ string) error {
+			return p.setDate(atoi(m[1]), atoi(m[2]), atoi(m[3]))
+		}),
+		newRule(`(\d{1,2})/(\d{1,2})(?:/(\d{4}|\d{2}))?`, func(p *parsed, m []string, _ string) error {
+			return p.setDate(year(m[3]), atoi(m[1]), atoi(m[2]))
+		}),
+		newRule(`(\d{1,2})[-.](\d{1,2})[-.](\d{4})`, func(p *parsed, m []string, _ string) error {
+			return p.setDate(atoi(m[3]), atoi(m[2]), atoi(m[1]))
+		}),
+		newRule(`(\d{1,2})\.(\d{1,2})\.(\d{2})`, func(p *parsed, m []string, _ string) error {
+			return p.setDate(year(m[3]), atoi(m[2]), atoi(m[1]))
+		}),
+		newRule(`(\d{4})-(\d{1,2})`, func(p *parsed, m []string, _ string) error {
+			return p.setDate(atoi(m[1]), atoi(m[2]), 1)
+		}),
+		newRule(`(\d{1,2})`+suffixPattern+`[ \t.-]*(`+monthPattern+`)(?:[ \t.-]*(\d{4}))?`, func(p *parsed, m []string, _ string) error {
+			return p.setDate(year(m[3]), monthNames[m[2]], atoi(m[1]))
+		}),
+		newRule(`(`+monthPattern+`)[ \t.-]*(\d{1,2})`+suffixPattern+`(?:,?[ \t.-]*(\d{4}))?`, func(p *parsed, m []string, _ string) error {
+			return p.setDate(year(m[3]), monthNames[m[1]], atoi(m[2]))
+		}),
+		newRule(`(`+monthPattern+`)[ \t.-]*(\d{4})`, func(p *parsed, m []string, _ string) error {
+			return p.setDate(atoi(m[2]), monthNames[m[1]], 1)
+		}),
+
+		// Times
+		newRule(`t?(\d{1,2}):(\d{2})(?::(\d{2})(?:[.,:](\d+))?)?(?:\s*([ap])\.?m(?:\.|\b))?`, func(p *parsed, m []string, _ string) error {
+			hour, err := meridian(atoi(m[1]), m[5])
+			if err != nil {
+				return err
+			}
+			second := 0
+			if m[3] != "" {
+				second = atoi(m[3])
+			}
+			return p.setTime(hour, atoi(m[2]), second, int(fractionNanos(m[4])/1000))
+		}),
+		newRule(`(\d{1,2})\s*([ap])\.?m(?:\.|\b)`, func(p *parsed, m []string, _ string) error {
+			hour, err := meridian(atoi(m[1]), m[2])
+			if err != nil {
+				return err
+			}
+			return p.setTime(hour, 0, 0, 0)
+		}),
+		newRule(`t?(\d{2})(\d{2})`, func(p *parsed, m []string, _ string) error {
+			return p.setTime(atoi(m[1]), atoi(m[2]), 0, 0)
+		}),
+
+		// Relative statements
+		newRule(`([+-]?)\s*(\d+)\s*(`+unitPattern+`)`, func(p *parsed, m []string, _ string) error {
+			n := atoi(m[2])
+			if m[1] == "-" {
+				n = -n
+			}
+			p.addRelative(n, m[3], false)
+			return nil
+		}),
+		newRule(`(`+reltextPattern+`)\s+(`+unitPattern+`)`, func(p *parsed, m []string, _ string) error {
+			p.addRelative(relativeNumbers[m[1]], m[2], true)
+			return nil
+		}),
+		newRule(`ago`, func(p *parsed, m []string, _ string) error {
+			p.years, p.months, p.days = -p.years, -p.months, -p.days
+			p.hours, p.minutes, p.seconds, p.micros = -p.hours, -p.minutes, -p.seconds, -p.micros
+			p.weekdays = -p.weekdays
+			return nil
+		}),
+		newRule(dayPattern, func(p *parsed, m []string, _ string) error {
+			if !p.haveWeekday {
+				p.setWeekday(dayNames[m[0]], weekdayThis, 1)
+			}
+			return nil
+		}),
+		newRule(monthPattern, func(p *parsed, m []string, _ string) error {
+			return p.setDate(unset, monthNames[m[0]], unset)
+		}),
+
+		// Timezones
+		newRule(`(?:gmt|utc)?[+-]\d{1,2}(?::?\d{2})?`, func(p *parsed, m []string, original string) error {
+			offset := strings.TrimLeft(original, "GMTUCgmtuc")
+			loc, ok := offsetZone(offset)
+			if !ok {
+				return errUnknownZone
+			}
+			return p.setZone(loc)
+		}),
+		newRule(`z`, func(p *parsed, m []string, _ string) error {
+			return p.setZone(time.UTC)
+		}),
+		newRule(`[a-z]+(?:/[a-z0-9_+-]+)*`, func(p *parsed, m []string, original string) error {
+			loc, ok := LoadTimezone(original)
+			if !ok {
+				return errUnknownZone
+			}
+			return p.setZone(loc)
+		}),
+	}
+}
+
+// ruleError is an error of a rule, reported at the rule's position
+type ruleError string
+
+func (e ruleError) Error() string { return string(e) }
+
+const errUnknownZone = ruleError("The timezone could not be found in the database")
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// year converts a 2- or 4-digit year; 00-69 are 2000-2069, 70-99 are
+// 1970-1999 and an empty string leaves the year unset
+func year(s string) int {
+	if s == "" {
+		return unset
+	}
+	y := atoi(s)
+	if len(s) == 2 {
+		if y < 70 {
+			return y + 2000
+		}
+		return y + 1900
+	}
+	return y
+}
+
+// fractionNanos converts the digits of a fraction of a second to
+// nanoseconds
+func fractionNanos(digits string) int64 {
+	if digits == "" {
+		return 0
+	}
+	digits = (digits + "000000000")[:9]
+	n, _ := strconv.ParseInt(digits, 10, 64)
+	return n
+}
+
+// meridian converts a 12-hour clock hour with an a or p marker
+func meridian(hour int, marker string) (int, error) {
+	if marker == "" {
+		return hour, nil
+	}
+	if hour < 1 || hour > 12 {
+		return 0, ruleError("Unexpected character")
+	}
+	hour %= 12
+	if marker == "p" {
+		hour += 12
+	}
+	return hour, nil
+}
+
+func (p *parsed) setDate(year, month, day int) error {
+	if p.haveDate {
+		return ruleError("Double date specification")
+	}
+	p.haveDate = true
+	p.year, p.month, p.day = year, month, day
+	return nil
+}
+
+func (p *parsed) setTime(hour, minute, second, micro int) error {
+	if p.haveTime {
+		return ruleError("Double time specification")
+	}
+	if hour > 24 || minute > 59 || second > 60 {
+		return ruleError("Unexpected character")
+	}
+	p.haveTime = true
+	p.hour, p.minute, p.second, p.micro = hour, minute, second, micro
+	return nil
+}
+
+func (p *parsed) setZone(loc *time.Location) error {
+	if p.haveZone {
+		return ruleError("Double timezone specification")
+	}
+	p.haveZone = true
+	p.zone = loc
+	return nil
+}
+
+func (p *parsed) setWeekday(day time.Weekday, behavior, count int) {
+	p.haveWeekday = true
+	p.weekday = day
+	p.weekdayBehavior = behavior
+	p.weekdayCount = count
+	p.resetTime = true
+}
+
+// addRelative adds n units; text is set for "next week" style statements
+func (p *parsed) addRelative(n int, unit string, text bool) {
+	if day, ok := dayNames[unit]; ok {
+		switch {
+		case n < 0:
+			p.setWeekday(day, weekdayLast, -n)
+		case n == 0:
+			p.setWeekday(day, weekdayThis, 1)
+		case text && n == 1:
+			p.setWeekday(day, weekdayNext, 1)
+		default:
+			p.setWeekday(day, weekdayNext, n)
+		}
+		return
+	}
+
+	unit = strings.TrimSuffix(unit, "s")
+	switch unit {
+	case "msec", "millisecond":
+		p.micros += n * 1000
+	case "usec", "microsecond", "µsec", "µ":
+		p.micros += n
+	case "sec", "second":
+		p.seconds += n
+	case "min", "minute":
+		p.minutes += n
+	case "hour":
+		p.hours += n
+	case "day":
+		p.days += n
+	case "week":
+		p.days += 7 * n
+		if text {
+			p.weekdayInWeek = true
+		}
+	case "fortnight", "forthnight":
+		p.days += 14 * n
+	case "month":
+		p.months += n
+	case "year":
+		p.years += n
+	case "weekday":
+		p.weekdays += n
+	}
+}
+
+// scan parses text into p
+func (p *parsed) scan(text string) error {
+	lower := strings.ToLower(text)
+	p.year, p.month, p.day = unset, unset, unset
+
+	for pos := 0; pos < len(lower); {
+		c := lower[pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == ',' {
+			pos++
+			continue
+		}
+
+		matched := false
+		for _, r := range rules {
+			loc := r.pattern.FindStringSubmatchIndex(lower[pos:])
+			if loc == nil || !isBoundary(lower, pos+loc[1]) {
+				continue
+			}
+			m := make([]string, len(loc)/2)
+			for i := range m {
+				if loc[2*i] >= 0 {
+					m[i] = lower[pos+loc[2*i] : pos+loc[2*i+1]]
+				}
+			}
+			if err := r.apply(p, m, text[pos:pos+loc[1]]); err != nil {
+				if e, ok := err.(ruleError); ok {
+					return &ParseError{Text: text, Position: pos, Message: string(e)}
+				}
+				return err
+			}
+			pos += loc[1]
+			matched = true
+			break
+		}
+		if !matched {
+			return &ParseError{Text: text, Position: pos, Message: "Unexpected character"}
+		}
+	}
+	return nil
+}
+
+// isBoundary reports whether a match may end at end: words and numbers
+// must not continue past it
+func isBoundary(s string, end int) bool {
+	if end == 0 || end >= len(s) {
+		return end > 0
+	}
+	last, next := s[end-1], s[end]
+	isLetter := func(c byte) bool { return c >= 'a' && c <= 'z' }
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	return !(isLetter(last) && isLetter(next)) && !(isDigit(last) && isDigit(next))
+}
+
+// Parse parses a date/time string the way strtotime() and the DateTime
+// constructor do: absolute dates and times, timezones, and relative
+// statements such as "+1 week 2 days", "next monday" or "last day of next
+// month". Fields the string does not give are taken from now, whose
+// location is used unless the string names a timezone.
+func Parse(text string, now time.Time) (time.Time, error) {
+	var p parsed
+	if err := p.scan(text); err != nil {
+		return time.Time{}, err
+	}
+	return p.resolve(now), nil
+}
+
+// resolve computes the time the parsed string denotes relative to now
+func (p *parsed) resolve(now time.Time) time.Time {
+	loc := now.Location()
+	if p.zone != nil {
+		loc = p.zone
+	}
+	base := now.In(loc)
+	if p.timestamp != nil {
+		base = *p.timestamp
+		if p.zone != nil {
+			base = base.In(p.zone)
+		}
+		loc = base.Location()
+	}
+
+	y, mo, d := base.Date()
+	year, month, day := y, int(mo), d
+	hour, minute, second := base.Clock()
+	micro := base.Nanosecond() / 1000
+
+	if p.year != unset {
+		year = p.year
+	}
+	if p.month != unset {
+		month = p.month
+	}
+	if p.day != unset {
+		day = p.day
+	}
+	switch {
+	case p.haveTime:
+		hour, minute, second, micro = p.hour, p.minute, p.second, p.micro
+	case p.haveDate || p.resetTime:
+		hour, minute, second, micro = 0, 0, 0, 0
+	}
+
+	year += p.years
+	month += p.months
+	switch p.firstLastDayOf {
+	case 1:
+		day = 1
+	case 2:
+		day = daysInMonth(time.Date(year, time.Month(month), 1, 0, 0, 0, 0, loc))
+	}
+	if p.nthWeekdayOf != 0 {
+		day = nthWeekdayOf(year, month, p.weekday, p.nthWeekdayOf)
+	}
+
+	// Relative days apply to the wall clock and relative times to the
+	// elapsed time, so "+1 hour" crosses a DST transition
+	t := wallTime(year, month, day+p.days, hour, minute, second, micro*1000, loc)
+	t = t.Add(elapsed(p.hours, p.minutes, p.seconds, p.micros))
+
+	if p.haveWeekday {
+		t = t.AddDate(0, 0, p.weekdayOffset(t))
+	}
+	if p.weekdays != 0 {
+		t = addWeekdays(t, p.weekdays)
+	}
+	return t
+}
+
+// weekdayOffset returns the number of days from t to the weekday of a
+// relative weekday statement
+func (p *parsed) weekdayOffset(t time.Time) int {
+	if p.weekdayInWeek {
+		// The weekday of t's Monday-to-Sunday week
+		target := int(p.weekday)
+		if target == 0 {
+			target = 7
+		}
+		return target - isoWeekday(t)
+	}
+
+	ahead := (int(p.weekday) - int(t.Weekday()) + 7) % 7
+	switch p.weekdayBehavior {
+	case weekdayLast:
+		behind := (int(t.Weekday()) - int(p.weekday) + 7) % 7
+		if behind == 0 {
+			behind = 7
+		}
+		return -behind - 7*(p.weekdayCount-1)
+	case weekdayNext:
+		if ahead == 0 {
+			ahead = 7
+		}
+		return ahead + 7*(p.weekdayCount-1)
+	}
+	return ahead
+}
+
+// nthWeekdayOf returns the day of the month of its nth weekday; n = -1
+// selects the last one
+func nthWeekdayOf(year, month int, weekday time.Weekday, n int) int {
+	if n < 0 {
+		last := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC)
+		return last.Day() - (int(last.Weekday())-int(weekday)+7)%7
+	}
+	first := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return 1 + (int(weekday)-int(first.Weekday())+7)%7 + 7*(n-1)
+}
+
+// addWeekdays moves t by n business days, skipping Saturdays and Sundays
+func addWeekdays(t time.Time, n int) time.Time {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		t = t.AddDate(0, 0, step)
+		if t.Weekday() != time.Saturday && t.Weekday() != time.Sunday {
+			n--
+		}
+	}
+	return t
+}
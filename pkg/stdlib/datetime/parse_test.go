@@ -0,0 +1,206 @@
+package datetime
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// Parser Tests
+// ============================================================================
+
+// parseBase is Friday 2024-03-15 14:30:45 UTC
+var parseBase = time.Date(2024, 3, 15, 14, 30, 45, 0, time.UTC)
+
+func TestParseAbsolute(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2024-01-02", "2024-01-02 00:00:00"},
+		{"2024-01-02 10:20:30", "2024-01-02 10:20:30"},
+		{"2024-01-02T10:20:30", "2024-01-02 10:20:30"},
+		{"2024/01/02", "2024-01-02 00:00:00"},
+		{"20240102", "2024-01-02 00:00:00"},
+		{"1/2/2024", "2024-01-02 00:00:00"},
+		{"02-01-2024", "2024-01-02 00:00:00"},
+		{"02.01.24", "2024-01-02 00:00:00"},
+		{"15 march 2023", "2023-03-15 00:00:00"},
+		{"March 15, 2023", "2023-03-15 00:00:00"},
+		{"1st Jan 2020 5pm", "2020-01-01 17:00:00"},
+		{"10:00", "2024-03-15 10:00:00"},
+		{"10:00:05.5", "2024-03-15 10:00:05"},
+		{"12am", "2024-03-15 00:00:00"},
+		{"@86400", "1970-01-02 00:00:00"},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.input, parseBase)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.input, err)
+			continue
+		}
+		if s := got.Format("2006-01-02 15:04:05"); s != tt.expected {
+			t.Errorf("Parse(%q) = %s, want %s", tt.input, s, tt.expected)
+		}
+	}
+}
+
+func TestParseRelative(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"now", "2024-03-15 14:30:45"},
+		{"today", "2024-03-15 00:00:00"},
+		{"tomorrow", "2024-03-16 00:00:00"},
+		{"yesterday noon", "2024-03-14 12:00:00"},
+		{"+1 day", "2024-03-16 14:30:45"},
+		{"-2 weeks", "2024-03-01 14:30:45"},
+		{"+1 week 2 days 4 hours 2 seconds", "2024-03-24 18:30:47"},
+		{"3 days ago", "2024-03-12 14:30:45"},
+		{"next month", "2024-04-15 14:30:45"},
+		{"last year", "2023-03-15 14:30:45"},
+		{"next monday", "2024-03-18 00:00:00"},
+		{"last friday", "2024-03-08 00:00:00"},
+		{"friday", "2024-03-15 00:00:00"},
+		{"monday next week", "2024-03-18 00:00:00"},
+		{"first day of next month", "2024-04-01 14:30:45"},
+		{"last day of february", "2024-02-29 00:00:00"},
+		{"second tuesday of april 2024", "2024-04-09 00:00:00"},
+		{"last sunday of march", "2024-03-31 00:00:00"},
+		{"+2 weekdays", "2024-03-19 14:30:45"},
+		{"2024-01-31 +1 month", "2024-03-02 00:00:00"},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.input, parseBase)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.input, err)
+			continue
+		}
+		if s := got.Format("2006-01-02 15:04:05"); s != tt.expected {
+			t.Errorf("Parse(%q) = %s, want %s", tt.input, s, tt.expected)
+		}
+	}
+}
+
+func TestParseTimezones(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2024-01-02 10:00:00 +02:00", "2024-01-02T10:00:00+02:00"},
+		{"2024-01-02T10:00:00Z", "2024-01-02T10:00:00Z"},
+		{"2024-01-02 10:00 Europe/Prague", "2024-01-02T10:00:00+01:00"},
+		{"2024-07-02 10:00 america/new_york", "2024-07-02T10:00:00-04:00"},
+		{"2024-01-02 10:00 CEST", "2024-01-02T10:00:00+02:00"},
+		{"2024-01-02 10:00 GMT+0530", "2024-01-02T10:00:00+05:30"},
+		{"2021-03-14 02:30 America/New_York", "2021-03-14T03:30:00-04:00"},
+		{"2021-03-14 01:30 America/New_York +1 hour", "2021-03-14T03:30:00-04:00"},
+		{"2021-03-13 02:30 America/New_York +1 day", "2021-03-14T03:30:00-04:00"},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.input, parseBase)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.input, err)
+			continue
+		}
+		if s := got.Format(time.RFC3339); s != tt.expected {
+			t.Errorf("Parse(%q) = %s, want %s", tt.input, s, tt.expected)
+		}
+	}
+
+	// A timestamp is in the offset +00:00
+	if got, _ := Parse("@0", parseBase); got.Location().String() != "+00:00" || ZoneType(got.Location()) != ZoneTypeOffset {
+		t.Errorf("Parse(\"@0\") is in %s", got.Location())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+	}{
+		{"invalid date string", "at position 0 (i): The timezone could not be found in the database"},
+		{"2024-01-02 2024-01-03", "Double date specification"},
+		{"10:00 11:00", "Double time specification"},
+		{"2024-01-02 Mars/Olympus", "The timezone could not be found in the database"},
+		{"+1 day !", "Unexpected character"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.input, parseBase)
+		if err == nil {
+			t.Errorf("Parse(%q) should fail", tt.input)
+			continue
+		}
+		if _, ok := err.(*ParseError); !ok {
+			t.Errorf("Parse(%q) error is %T, want *ParseError", tt.input, err)
+		}
+		if !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("Parse(%q) error = %q, want it to contain %q", tt.input, err.Error(), tt.message)
+		}
+	}
+}
+
+func TestParseFromFormat(t *testing.T) {
+	tests := []struct {
+		format   string
+		value    string
+		expected string
+	}{
+		{"Y-m-d H:i:s", "2024-01-02 10:20:30", "2024-01-02 10:20:30.000000"},
+		{"Y-m-d", "2024-01-02", "2024-01-02 14:30:45.000000"},
+		{"!Y-m-d", "2024-01-02", "2024-01-02 00:00:00.000000"},
+		{"Y-m-d|", "2024-01-02", "2024-01-02 00:00:00.000000"},
+		{"H\\h i\\m", "09h 05m", "2024-03-15 09:05:00.000000"},
+		{"j F Y g:i A", "5 March 2021 7:15 PM", "2021-03-05 19:15:00.000000"},
+		{"D, d M y", "Tue, 02 Jan 24", "2024-01-02 14:30:45.000000"},
+		{"Y-m-d H:i:s.u", "2024-01-02 10:20:30.125", "2024-01-02 10:20:30.125000"},
+		{"!z Y", "59 2024", "2024-02-29 00:00:00.000000"},
+		{"Y#m#d", "2024/01.02", "2024-01-02 14:30:45.000000"},
+		{"Y-m-d+", "2024-01-02 trailing", "2024-01-02 14:30:45.000000"},
+		{"U", "86400", "1970-01-02 00:00:00.000000"},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFromFormat(tt.format, tt.value, parseBase)
+		if err != nil {
+			t.Errorf("ParseFromFormat(%q, %q) error: %v", tt.format, tt.value, err)
+			continue
+		}
+		if s := got.Format("2006-01-02 15:04:05.000000"); s != tt.expected {
+			t.Errorf("ParseFromFormat(%q, %q) = %s, want %s", tt.format, tt.value, s, tt.expected)
+		}
+	}
+
+	got, err := ParseFromFormat("Y-m-d H:i e", "2024-01-02 10:00 Asia/Tokyo", parseBase)
+	if err != nil || got.Location().String() != "Asia/Tokyo" || got.Hour() != 10 {
+		t.Errorf("ParseFromFormat with a timezone = %v, %v", got, err)
+	}
+}
+
+func TestParseFromFormatErrors(t *testing.T) {
+	tests := []struct {
+		format  string
+		value   string
+		message string
+	}{
+		{"Y-m-d", "2024-01", "Not enough data available to satisfy format"},
+		{"Y-m-d", "2024-01-02 10:00", "Trailing data"},
+		{"Y-m-d", "2024/01/02", "The separation symbol could not be found"},
+		{"Y-m-d H:i", "2024-01-02 10:5", "A two digit minute could not be found"},
+		{"d M Y", "02 Foo 2024", "A textual month could not be found"},
+		{"Y-m-d e", "2024-01-02 Nowhere", "The timezone could not be found in the database"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseFromFormat(tt.format, tt.value, parseBase)
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("ParseFromFormat(%q, %q) error = %v, want %q", tt.format, tt.value, err, tt.message)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package datetime
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	// Embed the timezone database so identifiers resolve on every system
+	_ "time/tzdata"
+)
+
+// ============================================================================
+// Timezones
+// ============================================================================
+
+// PHP timezone types, as reported by var_dump() of DateTime objects
+const (
+	ZoneTypeOffset       = 1 // +02:00
+	ZoneTypeAbbreviation = 2 // CEST
+	ZoneTypeIdentifier   = 3 // Europe/Prague
+)
+
+// abbreviations maps the recognized timezone abbreviations to their UTC
+// offsets in seconds
+var abbreviations = map[string]int{
+	"utc": 0, "gmt": 0, "wet": 0, "west": 3600, "bst": 3600,
+	"cet": 3600, "cest": 7200, "met": 3600, "mest": 7200,
+	"eet": 7200, "eest": 10800, "msk": 10800,
+	"est": -5 * 3600, "edt": -4 * 3600, "cst": -6 * 3600, "cdt": -5 * 3600,
+	"mst": -7 * 3600, "mdt": -6 * 3600, "pst": -8 * 3600, "pdt": -7 * 3600,
+	"akst": -9 * 3600, "akdt": -8 * 3600, "hst": -10 * 3600,
+	"jst": 9 * 3600, "kst": 9 * 3600, "hkt": 8 * 3600, "awst": 8 * 3600,
+	"acst": 34200, "acdt": 37800, "aest": 10 * 3600, "aedt": 11 * 3600,
+	"nzst": 12 * 3600, "nzdt": 13 * 3600,
+}
+
+// defaultTimezone is the timezone of date() and of DateTime objects
+// created without one (atomic, since parallel tasks share it)
+var defaultTimezone atomic.Pointer[time.Location]
+
+func init() {
+	defaultTimezone.Store(time.UTC)
+}
+
+// DefaultTimezone returns the default timezone (UTC unless changed)
+func DefaultTimezone() *time.Location {
+	return defaultTimezone.Load()
+}
+
+// SetDefaultTimezone changes the default timezone
+func SetDefaultTimezone(loc *time.Location) {
+	defaultTimezone.Store(loc)
+}
+
+// LoadTimezone resolves a timezone name: an identifier from the timezone
+// database (case-insensitive), an abbreviation or a UTC offset
+func LoadTimezone(name string) (*time.Location, bool) {
+	if loc, ok := offsetZone(name); ok {
+		return loc, true
+	}
+	if strings.EqualFold(name, "UTC") {
+		return time.UTC, true
+	}
+	if offset, ok := abbreviations[strings.ToLower(name)]; ok {
+		return time.FixedZone(strings.ToUpper(name), offset), true
+	}
+	if name == "" || strings.EqualFold(name, "Local") {
+		return nil, false
+	}
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc, true
+	}
+	if loc, err := time.LoadLocation(canonicalZoneName(name)); err == nil {
+		return loc, true
+	}
+	return nil, false
+}
+
+// canonicalZoneName capitalizes the words of a timezone identifier the way
+// the database spells them: "america/new_york" becomes "America/New_York"
+func canonicalZoneName(name string) string {
+	b := []byte(strings.ToLower(name))
+	start := true
+	for i, c := range b {
+		if start && c >= 'a' && c <= 'z' {
+			b[i] = c - 'a' + 'A'
+		}
+		start = c == '/' || c == '_' || c == '-'
+	}
+	return string(b)
+}
+
+// offsetZone parses a UTC offset such as +02:00, -0530 or +2
+func offsetZone(s string) (*time.Location, bool) {
+	if len(s) < 2 || (s[0] != '+' && s[0] != '-') {
+		return nil, false
+	}
+	digits := strings.Replace(s[1:], ":", "", 1)
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return nil, false
+		}
+	}
+	var hours, minutes int
+	switch len(digits) {
+	case 1, 2:
+		fmt.Sscanf(digits, "%d", &hours)
+	case 3:
+		fmt.Sscanf(digits, "%1d%2d", &hours, &minutes)
+	case 4:
+		fmt.Sscanf(digits, "%2d%2d", &hours, &minutes)
+	default:
+		return nil, false
+	}
+	if hours > 99 || minutes > 59 {
+		return nil, false
+	}
+	offset := hours*3600 + minutes*60
+	if s[0] == '-' {
+		offset = -offset
+	}
+	return FixedOffset(offset), true
+}
+
+// FixedOffset returns a timezone with a fixed UTC offset, named like
+// +02:00
+func FixedOffset(offset int) *time.Location {
+	return time.FixedZone(formatOffset(offset, true), offset)
+}
+
+// formatOffset formats a UTC offset in seconds as +0200 or +02:00
+func formatOffset(offset int, colon bool) string {
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	if colon {
+		return fmt.Sprintf("%c%02d:%02d", sign, offset/3600, offset%3600/60)
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, offset/3600, offset%3600/60)
+}
+
+// ZoneType returns the PHP timezone type of a location
+func ZoneType(loc *time.Location) int {
+	name := loc.String()
+	if strings.HasPrefix(name, "+") || strings.HasPrefix(name, "-") {
+		return ZoneTypeOffset
+	}
+	if _, ok := abbreviations[strings.ToLower(name)]; ok && loc != time.UTC {
+		return ZoneTypeAbbreviation
+	}
+	return ZoneTypeIdentifier
+}
@@ -0,0 +1,124 @@
+package file
+
+import (
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// StreamContextResourceType is the resource type label of stream
+// contexts, as reported by get_resource_type()
+const StreamContextResourceType = "stream-context"
+
+// ============================================================================
+// Stream Contexts
+// ============================================================================
+
+// StreamContext holds the options of a stream context by wrapper, such as
+// $options['http']['method'], and its parameters
+type StreamContext struct {
+	Options *types.Array
+	Params  *types.Array
+}
+
+// ContextOf returns the stream context behind a resource value
+func ContextOf(value *types.Value) (*StreamContext, bool) {
+	if value == nil || value.Type() != types.TypeResource {
+		return nil, false
+	}
+	res := value.ToResource()
+	if res.Type() != StreamContextResourceType {
+		return nil, false
+	}
+	ctx, ok := res.Data().(*StreamContext)
+	return ctx, ok
+}
+
+// Option returns an option of a wrapper; false if it is not set
+func (c *StreamContext) Option(wrapper, name string) (*types.Value, bool) {
+	if c == nil {
+		return nil, false
+	}
+	options, ok := c.Options.Get(types.NewString(wrapper))
+	if !ok || options.Type() != types.TypeArray {
+		return nil, false
+	}
+	return options.ToArray().Get(types.NewString(name))
+}
+
+// setOption sets an option of a wrapper
+func (c *StreamContext) setOption(wrapper, name string, value *types.Value) {
+	key := types.NewString(wrapper)
+	options, ok := c.Options.Get(key)
+	if !ok || options.Type() != types.TypeArray {
+		options = types.NewArray(types.NewEmptyArray())
+		c.Options.Set(key, options)
+	}
+	options.ToArray().Set(types.NewString(name), value.Copy())
+}
+
+// setOptions merges an array of wrapper => options arrays
+func (c *StreamContext) setOptions(options *types.Array) {
+	options.Each(func(wrapper, values *types.Value) bool {
+		if values.Type() == types.TypeArray {
+			values.ToArray().Each(func(name, value *types.Value) bool {
+				c.setOption(wrapper.ToString(), name.ToString(), value)
+				return true
+			})
+		}
+		return true
+	})
+}
+
+// StreamContextCreate creates a stream context
+// stream_context_create(?array $options = null, ?array $params = null): resource
+func StreamContextCreate(args ...*types.Value) *types.Value {
+	ctx := &StreamContext{Options: types.NewEmptyArray(), Params: types.NewEmptyArray()}
+	if len(args) > 0 && args[0].Type() == types.TypeArray {
+		ctx.setOptions(args[0].ToArray())
+	}
+	if len(args) > 1 && args[1].Type() == types.TypeArray {
+		ctx.Params = args[1].ToArray().Copy()
+	}
+	return types.NewResource(types.NewResourceHandle(StreamContextResourceType, ctx))
+}
+
+// StreamContextGetOptions returns the options of a stream context
+// stream_context_get_options(resource $stream_or_context): array
+func StreamContextGetOptions(context *types.Value) *types.Value {
+	ctx, ok := ContextOf(context)
+	if !ok {
+		return types.NewBool(false)
+	}
+	return types.NewArray(ctx.Options.Copy())
+}
+
+// StreamContextSetOption sets one option, or several given as an array
+// stream_context_set_option(resource $context, string $wrapper_name, string $option_name, mixed $value): bool
+// stream_context_set_option(resource $context, array $options): bool
+func StreamContextSetOption(context *types.Value, wrapper *types.Value, args ...*types.Value) *types.Value {
+	ctx, ok := ContextOf(context)
+	if !ok {
+		return types.NewBool(false)
+	}
+	if wrapper.Type() == types.TypeArray {
+		ctx.setOptions(wrapper.ToArray())
+		return types.NewBool(true)
+	}
+	if len(args) < 2 {
+		return types.NewBool(false)
+	}
+	ctx.setOption(wrapper.ToString(), args[0].ToString(), args[1])
+	return types.NewBool(true)
+}
+
+// StreamContextGetParams returns the parameters and options of a stream
+// context
+// stream_context_get_params(resource $context): array
+func StreamContextGetParams(context *types.Value) *types.Value {
+	ctx, ok := ContextOf(context)
+	if !ok {
+		return types.NewBool(false)
+	}
+	params := ctx.Params.Copy()
+	params.Set(types.NewString("options"), types.NewArray(ctx.Options.Copy()))
+	return types.NewArray(params)
+}
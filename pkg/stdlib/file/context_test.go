@@ -0,0 +1,36 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestStreamContextOptions(t *testing.T) {
+	options := types.NewArrayFromMap(map[interface{}]*types.Value{
+		"http": types.NewArray(types.NewArrayFromMap(map[interface{}]*types.Value{"method": types.NewString("POST")})),
+	})
+	context := StreamContextCreate(types.NewArray(options))
+	if context.ToResource().Type() != StreamContextResourceType {
+		t.Fatalf("resource type = %s", context.ToResource().Type())
+	}
+
+	StreamContextSetOption(context, types.NewString("http"), types.NewString("timeout"), types.NewFloat(1.5))
+	ctx, _ := ContextOf(context)
+	if method, ok := ctx.Option("http", "method"); !ok || method.ToString() != "POST" {
+		t.Errorf("method = %v", method)
+	}
+	opts := httpOptionsOf(ctx)
+	if opts.method != "POST" || opts.timeout.Seconds() != 1.5 || !opts.followLocation || opts.maxRedirects != defaultMaxRedirects {
+		t.Errorf("httpOptionsOf = %+v", opts)
+	}
+
+	got := StreamContextGetOptions(context).ToArray()
+	http, _ := got.Get(types.NewString("http"))
+	if http.ToArray().Len() != 2 {
+		t.Errorf("stream_context_get_options() = %v", got)
+	}
+	if StreamContextGetOptions(types.NewString("x")).ToBool() {
+		t.Errorf("a non-context is rejected")
+	}
+}
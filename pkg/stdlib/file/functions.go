@@ -9,6 +9,34 @@ import (
 	"github.com/krizos/php-go/pkg/types"
 )
 
+// ============================================================================
+// Constants
+// ============================================================================
+
+// Flags for file() and file_put_contents()
+const (
+	FILE_USE_INCLUDE_PATH   = 1
+	FILE_IGNORE_NEW_LINES   = 2
+	FILE_SKIP_EMPTY_LINES   = 4
+	FILE_APPEND             = 8
+	FILE_NO_DEFAULT_CONTEXT = 16
+)
+
+// Operations of flock(), also accepted by file_put_contents() (LOCK_EX)
+const (
+	LOCK_SH = 1
+	LOCK_EX = 2
+	LOCK_UN = 3
+	LOCK_NB = 4
+)
+
+// Whence values for fseek()
+const (
+	SEEK_SET = io.SeekStart
+	SEEK_CUR = io.SeekCurrent
+	SEEK_END = io.SeekEnd
+)
+
 // ============================================================================
 // File Reading Functions
 // ============================================================================
@@ -37,9 +65,8 @@ func FilePutContents(filename *types.Value, data *types.Value, args ...*types.Va
 		flags = int(args[0].ToInt())
 	}
 
-	// FILE_APPEND flag (8)
 	var writeFlags int
-	if flags&8 != 0 {
+	if flags&FILE_APPEND != 0 {
 		writeFlags = os.O_APPEND | os.O_CREATE | os.O_WRONLY
 	} else {
 		writeFlags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
@@ -74,27 +101,26 @@ func File(filename *types.Value, args ...*types.Value) *types.Value {
 		flags = int(args[0].ToInt())
 	}
 
-	// Split into lines
-	content := string(data)
-	lines := strings.Split(content, "\n")
-
-	// FILE_IGNORE_NEW_LINES flag (2)
-	skipNewlines := flags&2 != 0
+	// Split into lines, keeping the terminators; a trailing newline does
+	// not start another line
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
 
-	// FILE_SKIP_EMPTY_LINES flag (4)
-	skipEmpty := flags&4 != 0
+	skipNewlines := flags&FILE_IGNORE_NEW_LINES != 0
+	skipEmpty := flags&FILE_SKIP_EMPTY_LINES != 0
 
 	arr := types.NewEmptyArray()
 	for _, line := range lines {
-		if skipEmpty && line == "" {
+		if skipNewlines {
+			line = strings.TrimSuffix(line, "\n")
+		}
+		if skipEmpty && skipNewlines && line == "" {
 			continue
 		}
 
-		if skipNewlines {
-			arr.Append(types.NewString(line))
-		} else {
-			arr.Append(types.NewString(line + "\n"))
-		}
+		arr.Append(types.NewString(line))
 	}
 
 	return types.NewArray(arr)
@@ -119,104 +145,105 @@ func Readfile(filename *types.Value) *types.Value {
 // File Handle Functions
 // ============================================================================
 
-// Fopen opens a file or URL
+// Fopen opens a file or php:// stream
 // fopen(string $filename, string $mode): resource|false
 func Fopen(filename *types.Value, mode *types.Value) *types.Value {
-	path := filename.ToString()
-	modeStr := mode.ToString()
-
-	var flags int
-	switch modeStr {
-	case "r":
-		flags = os.O_RDONLY
-	case "r+":
-		flags = os.O_RDWR
-	case "w":
-		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
-	case "w+":
-		flags = os.O_RDWR | os.O_CREATE | os.O_TRUNC
-	case "a":
-		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
-	case "a+":
-		flags = os.O_RDWR | os.O_CREATE | os.O_APPEND
-	case "x":
-		flags = os.O_WRONLY | os.O_CREATE | os.O_EXCL
-	case "x+":
-		flags = os.O_RDWR | os.O_CREATE | os.O_EXCL
-	default:
+	stream, err := OpenStream(filename.ToString(), mode.ToString())
+	if err != nil {
 		return types.NewBool(false)
 	}
 
-	file, err := os.OpenFile(path, flags, 0644)
-	if err != nil {
-		return types.NewBool(false)
+	return types.NewResource(types.NewStreamResource(stream))
+}
+
+// streamOf returns the open stream behind a resource value
+func streamOf(value *types.Value) (*types.Stream, bool) {
+	if value.Type() != types.TypeResource {
+		return nil, false
+	}
+
+	res := value.ToResource()
+	if res.Type() != types.StreamResourceType {
+		return nil, false
 	}
 
-	resource := types.NewResourceHandle("file", file)
-	return types.NewResource(resource)
+	stream, ok := res.Data().(*types.Stream)
+	return stream, ok
 }
 
 // Fclose closes an open file pointer
 // fclose(resource $stream): bool
 func Fclose(stream *types.Value) *types.Value {
-	if stream.Type() != types.TypeResource {
+	if _, ok := streamOf(stream); !ok {
 		return types.NewBool(false)
 	}
 
-	res := stream.ToResource()
-	if res.Type() != "file" {
-		return types.NewBool(false)
-	}
-
-	if file, ok := res.Data().(*os.File); ok {
-		err := file.Close()
-		return types.NewBool(err == nil)
-	}
-
-	return types.NewBool(false)
+	return types.NewBool(stream.ToResource().Close() == nil)
 }
 
 // Fread reads from file pointer
 // fread(resource $stream, int $length): string|false
 func Fread(stream *types.Value, length *types.Value) *types.Value {
-	if stream.Type() != types.TypeResource {
+	s, ok := streamOf(stream)
+	if !ok {
 		return types.NewBool(false)
 	}
 
-	res := stream.ToResource()
-	if res.Type() != "file" {
+	n := int(length.ToInt())
+	if n <= 0 {
 		return types.NewBool(false)
 	}
 
-	file, ok := res.Data().(*os.File)
-	if !ok {
+	data, err := s.Read(n)
+	if err != nil {
 		return types.NewBool(false)
 	}
 
-	n := int(length.ToInt())
-	buf := make([]byte, n)
+	return types.NewString(string(data))
+}
 
-	bytesRead, err := file.Read(buf)
-	if err != nil && err != io.EOF {
+// StreamGetContents reads the rest of a stream, or at most length bytes,
+// starting at offset when it is not negative
+// stream_get_contents(resource $stream, ?int $length = null, int $offset = -1): string|false
+func StreamGetContents(stream *types.Value, args ...*types.Value) *types.Value {
+	s, ok := streamOf(stream)
+	if !ok {
 		return types.NewBool(false)
 	}
 
-	return types.NewString(string(buf[:bytesRead]))
+	length := int64(-1)
+	if len(args) > 0 && !args[0].IsNull() {
+		length = args[0].ToInt()
+	}
+	if len(args) > 1 && args[1].ToInt() >= 0 {
+		if _, err := s.Seek(args[1].ToInt(), io.SeekStart); err != nil {
+			return types.NewBool(false)
+		}
+	}
+
+	var buf []byte
+	for length < 0 || int64(len(buf)) < length {
+		chunk := 8192
+		if length >= 0 {
+			chunk = int(min(length-int64(len(buf)), 8192))
+		}
+		// An empty read is the end of the stream or a socket timeout
+		data, err := s.Read(chunk)
+		if err != nil {
+			return types.NewBool(false)
+		}
+		if len(data) == 0 {
+			break
+		}
+		buf = append(buf, data...)
+	}
+	return types.NewString(string(buf))
 }
 
 // Fwrite writes to file pointer
 // fwrite(resource $stream, string $data, int $length = null): int|false
 func Fwrite(stream *types.Value, data *types.Value, args ...*types.Value) *types.Value {
-	if stream.Type() != types.TypeResource {
-		return types.NewBool(false)
-	}
-
-	res := stream.ToResource()
-	if res.Type() != "file" {
-		return types.NewBool(false)
-	}
-
-	file, ok := res.Data().(*os.File)
+	s, ok := streamOf(stream)
 	if !ok {
 		return types.NewBool(false)
 	}
@@ -224,14 +251,17 @@ func Fwrite(stream *types.Value, data *types.Value, args ...*types.Value) *types
 	content := data.ToString()
 
 	// Optional length parameter
-	if len(args) > 0 {
+	if len(args) > 0 && !args[0].IsNull() {
 		length := int(args[0].ToInt())
+		if length < 0 {
+			length = 0
+		}
 		if length < len(content) {
 			content = content[:length]
 		}
 	}
 
-	n, err := file.WriteString(content)
+	n, err := s.Write([]byte(content))
 	if err != nil {
 		return types.NewBool(false)
 	}
@@ -242,68 +272,109 @@ func Fwrite(stream *types.Value, data *types.Value, args ...*types.Value) *types
 // Fgets reads line from file pointer
 // fgets(resource $stream, int $length = null): string|false
 func Fgets(stream *types.Value, args ...*types.Value) *types.Value {
-	if stream.Type() != types.TypeResource {
+	s, ok := streamOf(stream)
+	if !ok {
 		return types.NewBool(false)
 	}
 
-	res := stream.ToResource()
-	if res.Type() != "file" {
+	length := 0
+	if len(args) > 0 && !args[0].IsNull() {
+		length = int(args[0].ToInt())
+		if length <= 0 {
+			return types.NewBool(false)
+		}
+	}
+
+	line, err := s.ReadLine(length)
+	if err != nil || len(line) == 0 {
 		return types.NewBool(false)
 	}
 
-	file, ok := res.Data().(*os.File)
+	return types.NewString(string(line))
+}
+
+// Fgetc reads character from file pointer
+// fgetc(resource $stream): string|false
+func Fgetc(stream *types.Value) *types.Value {
+	s, ok := streamOf(stream)
 	if !ok {
 		return types.NewBool(false)
 	}
 
-	// Read one byte at a time until newline
-	var line strings.Builder
-	buf := make([]byte, 1)
+	data, err := s.Read(1)
+	if err != nil || len(data) == 0 {
+		return types.NewBool(false)
+	}
 
-	for {
-		n, err := file.Read(buf)
-		if err != nil {
-			if err == io.EOF && line.Len() > 0 {
-				break
-			}
-			return types.NewBool(false)
-		}
+	return types.NewString(string(data))
+}
 
-		if n > 0 {
-			line.WriteByte(buf[0])
-			if buf[0] == '\n' {
-				break
-			}
-		}
+// Feof tests for end-of-file on a file pointer
+// feof(resource $stream): bool
+func Feof(stream *types.Value) *types.Value {
+	s, ok := streamOf(stream)
+	if !ok {
+		return types.NewBool(true)
 	}
 
-	return types.NewString(line.String())
+	return types.NewBool(s.EOF())
 }
 
-// Fgetc reads character from file pointer
-// fgetc(resource $stream): string|false
-func Fgetc(stream *types.Value) *types.Value {
-	if stream.Type() != types.TypeResource {
-		return types.NewBool(false)
+// Fseek seeks on a file pointer
+// fseek(resource $stream, int $offset, int $whence = SEEK_SET): int
+func Fseek(stream *types.Value, offset *types.Value, args ...*types.Value) *types.Value {
+	s, ok := streamOf(stream)
+	if !ok {
+		return types.NewInt(-1)
+	}
+
+	whence := SEEK_SET
+	if len(args) > 0 {
+		whence = int(args[0].ToInt())
+	}
+	if whence != SEEK_SET && whence != SEEK_CUR && whence != SEEK_END {
+		return types.NewInt(-1)
+	}
+
+	if _, err := s.Seek(offset.ToInt(), whence); err != nil {
+		return types.NewInt(-1)
 	}
 
-	res := stream.ToResource()
-	if res.Type() != "file" {
+	return types.NewInt(0)
+}
+
+// Ftell returns the current position of the file read/write pointer
+// ftell(resource $stream): int|false
+func Ftell(stream *types.Value) *types.Value {
+	s, ok := streamOf(stream)
+	if !ok {
 		return types.NewBool(false)
 	}
 
-	file, ok := res.Data().(*os.File)
+	return types.NewInt(s.Tell())
+}
+
+// Rewind rewinds the position of a file pointer
+// rewind(resource $stream): bool
+func Rewind(stream *types.Value) *types.Value {
+	s, ok := streamOf(stream)
 	if !ok {
 		return types.NewBool(false)
 	}
 
-	buf := make([]byte, 1)
-	n, err := file.Read(buf)
-	if err != nil || n == 0 {
+	_, err := s.Seek(0, SEEK_SET)
+	return types.NewBool(err == nil)
+}
+
+// Fflush flushes the output to a file
+// fflush(resource $stream): bool
+func Fflush(stream *types.Value) *types.Value {
+	s, ok := streamOf(stream)
+	if !ok {
 		return types.NewBool(false)
 	}
 
-	return types.NewString(string(buf[0]))
+	return types.NewBool(s.Flush() == nil)
 }
 
 // ============================================================================
@@ -318,6 +318,21 @@ func TestFgetc(t *testing.T) {
 	Fclose(handle)
 }
 
+func TestStreamGetContents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test")
+	os.WriteFile(path, []byte("Hello, World!"), 0644)
+	handle := Fopen(types.NewString(path), types.NewString("r"))
+	defer Fclose(handle)
+
+	Fgetc(handle)
+	if rest := StreamGetContents(handle); rest.ToString() != "ello, World!" {
+		t.Errorf("StreamGetContents() = %v", rest)
+	}
+	if part := StreamGetContents(handle, types.NewInt(5), types.NewInt(7)); part.ToString() != "World" {
+		t.Errorf("StreamGetContents(5, 7) = %v", part)
+	}
+}
+
 // ============================================================================
 // File Information Tests
 // ============================================================================
@@ -751,3 +766,152 @@ func TestCopy(t *testing.T) {
 		t.Errorf("Copied file content = %v, want %v", string(data), content)
 	}
 }
+
+// ============================================================================
+// Stream Position Tests
+// ============================================================================
+
+func TestFeofFgetsLoop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lines.txt")
+	os.WriteFile(path, []byte("a\nb\n"), 0644)
+
+	handle := Fopen(types.NewString(path), types.NewString("rb"))
+	defer Fclose(handle)
+
+	var lines []string
+	for !Feof(handle).ToBool() {
+		line := Fgets(handle)
+		if line.Type() == types.TypeBool {
+			continue
+		}
+		lines = append(lines, line.ToString())
+	}
+	if len(lines) != 2 || lines[0] != "a\n" || lines[1] != "b\n" {
+		t.Errorf("Fgets loop read %q", lines)
+	}
+}
+
+func TestFseekFtellRewind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seek.txt")
+	os.WriteFile(path, []byte("0123456789"), 0644)
+
+	handle := Fopen(types.NewString(path), types.NewString("r"))
+	defer Fclose(handle)
+
+	if r := Fseek(handle, types.NewInt(4)); r.ToInt() != 0 {
+		t.Errorf("Fseek() = %d, want 0", r.ToInt())
+	}
+	if r := Fread(handle, types.NewInt(2)); r.ToString() != "45" {
+		t.Errorf("Fread() after seek = %q", r.ToString())
+	}
+	if r := Ftell(handle); r.ToInt() != 6 {
+		t.Errorf("Ftell() = %d, want 6", r.ToInt())
+	}
+	Fseek(handle, types.NewInt(-1), types.NewInt(SEEK_CUR))
+	if r := Fgetc(handle); r.ToString() != "5" {
+		t.Errorf("Fgetc() after SEEK_CUR = %q, want \"5\"", r.ToString())
+	}
+	Fseek(handle, types.NewInt(-3), types.NewInt(SEEK_END))
+	if r := Fread(handle, types.NewInt(10)); r.ToString() != "789" {
+		t.Errorf("Fread() after SEEK_END = %q", r.ToString())
+	}
+	if r := Fseek(handle, types.NewInt(-20)); r.ToInt() != -1 {
+		t.Errorf("Fseek() before start = %d, want -1", r.ToInt())
+	}
+	if !Rewind(handle).ToBool() || Ftell(handle).ToInt() != 0 {
+		t.Error("Rewind() should move to the start")
+	}
+}
+
+func TestFreadFwriteBinarySafe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "binary.dat")
+	content := "\x00\xff\r\n\x01bin"
+
+	handle := Fopen(types.NewString(path), types.NewString("w+b"))
+	defer Fclose(handle)
+
+	if r := Fwrite(handle, types.NewString(content)); r.ToInt() != int64(len(content)) {
+		t.Errorf("Fwrite() = %d, want %d", r.ToInt(), len(content))
+	}
+	Rewind(handle)
+	if r := Fread(handle, types.NewInt(100)); r.ToString() != content {
+		t.Errorf("Fread() = %q, want %q", r.ToString(), content)
+	}
+}
+
+func TestFcloseInvalidatesHandle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "closed.txt")
+	os.WriteFile(path, []byte("data"), 0644)
+
+	handle := Fopen(types.NewString(path), types.NewString("r"))
+	if !Fclose(handle).ToBool() {
+		t.Fatal("Fclose() should return true")
+	}
+	if Fclose(handle).ToBool() {
+		t.Error("Fclose() on a closed handle should return false")
+	}
+	if Fread(handle, types.NewInt(1)).Type() != types.TypeBool {
+		t.Error("Fread() on a closed handle should return false")
+	}
+}
+
+func TestFileTrailingNewline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lines.txt")
+	os.WriteFile(path, []byte("a\n\nb\n"), 0644)
+
+	arr := File(types.NewString(path)).ToArray()
+	if arr.Len() != 3 {
+		t.Fatalf("File() returned %d lines, want 3", arr.Len())
+	}
+	last, _ := arr.Get(types.NewInt(2))
+	if last.ToString() != "b\n" {
+		t.Errorf("Last line = %q, want %q", last.ToString(), "b\n")
+	}
+
+	arr = File(types.NewString(path), types.NewInt(FILE_IGNORE_NEW_LINES|FILE_SKIP_EMPTY_LINES)).ToArray()
+	if arr.Len() != 2 {
+		t.Errorf("File(IGNORE_NEW_LINES|SKIP_EMPTY_LINES) returned %d lines, want 2", arr.Len())
+	}
+}
+
+func TestFopenMemory(t *testing.T) {
+	handle := Fopen(types.NewString("php://memory"), types.NewString("w+"))
+	defer Fclose(handle)
+
+	Fwrite(handle, types.NewString("hello world"))
+	Fseek(handle, types.NewInt(6))
+	Fwrite(handle, types.NewString("WORLD!"))
+	Rewind(handle)
+	if r := StreamGetContents(handle); r.ToString() != "hello WORLD!" {
+		t.Errorf("StreamGetContents() = %q, want \"hello WORLD!\"", r.ToString())
+	}
+	if r := Fseek(handle, types.NewInt(100)); r.ToInt() != -1 {
+		t.Errorf("Fseek() past the end = %d, want -1", r.ToInt())
+	}
+	Fseek(handle, types.NewInt(-6), types.NewInt(SEEK_END))
+	if r := Fread(handle, types.NewInt(5)); r.ToString() != "WORLD" {
+		t.Errorf("Fread() after SEEK_END = %q", r.ToString())
+	}
+
+	meta := StreamGetMetaData(handle).ToArray()
+	if streamType, _ := meta.Get(types.NewString("stream_type")); streamType.ToString() != "MEMORY" {
+		t.Errorf("stream_type = %q, want MEMORY", streamType.ToString())
+	}
+
+	// Opened read-only, a memory stream is not writable
+	readOnly := Fopen(types.NewString("php://temp"), types.NewString("r"))
+	defer Fclose(readOnly)
+	if Fwrite(readOnly, types.NewString("x")).ToBool() {
+		t.Error("Fwrite() to a read-only php://temp stream should fail")
+	}
+}
+
+func TestOpenStreamErrors(t *testing.T) {
+	_, err := OpenStream(filepath.Join(t.TempDir(), "missing"), "r")
+	if err == nil || err.Error() != "No such file or directory" {
+		t.Errorf("OpenStream() of a missing file: %v", err)
+	}
+	if _, err := OpenStream("php://unknown", "r"); err == nil {
+		t.Error("OpenStream() of an unknown php:// stream should fail")
+	}
+}
@@ -0,0 +1,261 @@
+package file
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// Defaults of the "http" context options
+const (
+	defaultSocketTimeout = 60 * time.Second
+	defaultMaxRedirects  = 20
+)
+
+// ============================================================================
+// HTTP Streams
+// ============================================================================
+
+// IsHTTPURL reports whether a filename is opened by the http:// and
+// https:// wrappers
+func IsHTTPURL(filename string) bool {
+	lower := strings.ToLower(filename)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// HTTPResponse is an open http:// stream
+type HTTPResponse struct {
+	// Headers are the status lines and headers of every response received,
+	// redirects included, as in $http_response_header
+	Headers []string
+	// Body is the body of the last response; nil if the request failed
+	Body io.ReadCloser
+}
+
+// OpenHTTP sends a request with the "http" and "ssl" options of a context,
+// which may be nil. The error's message is what PHP reports after
+// "Failed to open stream: "; the headers received so far are returned
+// along with it.
+func OpenHTTP(rawURL string, ctx *StreamContext) (*HTTPResponse, error) {
+	opts := httpOptionsOf(ctx)
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Host == "" {
+		return nil, errors.New("HTTP request failed!")
+	}
+
+	client := &http.Client{
+		Timeout: opts.timeout,
+		Transport: &http.Transport{
+			TLSClientConfig:    &tls.Config{InsecureSkipVerify: !opts.verifyPeer},
+			DisableCompression: true,
+			DisableKeepAlives:  true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	result := &HTTPResponse{}
+	method, body := opts.method, opts.content
+	for redirects := 0; ; redirects++ {
+		resp, err := sendHTTP(client, method, target, body, opts)
+		if err != nil {
+			return result, httpError(err, target)
+		}
+		result.Headers = append(result.Headers, responseHeaderLines(resp)...)
+
+		location := resp.Header.Get("Location")
+		if opts.followLocation && location != "" && isRedirect(resp.StatusCode) {
+			resp.Body.Close()
+			if redirects >= opts.maxRedirects {
+				return result, errors.New("Redirection limit reached, aborting")
+			}
+			next, err := target.Parse(location)
+			if err != nil {
+				return result, errors.New("HTTP request failed!")
+			}
+			target = next
+			if resp.StatusCode != 307 && resp.StatusCode != 308 && method != "HEAD" {
+				method, body = "GET", ""
+			}
+			continue
+		}
+
+		if resp.StatusCode >= 400 && !opts.ignoreErrors {
+			resp.Body.Close()
+			return result, fmt.Errorf("HTTP request failed! %s %s", resp.Proto, resp.Status)
+		}
+		result.Body = resp.Body
+		return result, nil
+	}
+}
+
+// httpOptions are the "http" and "ssl" options of a context
+type httpOptions struct {
+	method         string
+	headers        []string
+	content        string
+	userAgent      string
+	timeout        time.Duration
+	followLocation bool
+	maxRedirects   int
+	ignoreErrors   bool
+	verifyPeer     bool
+}
+
+// httpOptionsOf reads the options of a context, applying PHP's defaults
+func httpOptionsOf(ctx *StreamContext) httpOptions {
+	opts := httpOptions{
+		method:         "GET",
+		timeout:        defaultSocketTimeout,
+		followLocation: true,
+		maxRedirects:   defaultMaxRedirects,
+		verifyPeer:     true,
+	}
+	if v, ok := ctx.Option("http", "method"); ok {
+		opts.method = strings.ToUpper(v.ToString())
+	}
+	if v, ok := ctx.Option("http", "header"); ok {
+		if v.Type() == types.TypeArray {
+			v.ToArray().Each(func(_, line *types.Value) bool {
+				opts.headers = append(opts.headers, line.ToString())
+				return true
+			})
+		} else {
+			opts.headers = strings.Split(strings.ReplaceAll(v.ToString(), "\r\n", "\n"), "\n")
+		}
+	}
+	if v, ok := ctx.Option("http", "content"); ok {
+		opts.content = v.ToString()
+	}
+	if v, ok := ctx.Option("http", "user_agent"); ok {
+		opts.userAgent = v.ToString()
+	}
+	if v, ok := ctx.Option("http", "timeout"); ok {
+		opts.timeout = time.Duration(v.ToFloat() * float64(time.Second))
+	}
+	if v, ok := ctx.Option("http", "follow_location"); ok {
+		opts.followLocation = v.ToBool()
+	}
+	if v, ok := ctx.Option("http", "max_redirects"); ok {
+		opts.maxRedirects = int(v.ToInt())
+	}
+	if v, ok := ctx.Option("http", "ignore_errors"); ok {
+		opts.ignoreErrors = v.ToBool()
+	}
+	if v, ok := ctx.Option("ssl", "verify_peer"); ok {
+		opts.verifyPeer = v.ToBool()
+	}
+	return opts
+}
+
+// sendHTTP sends one request of OpenHTTP
+func sendHTTP(client *http.Client, method string, target *url.URL, content string, opts httpOptions) (*http.Response, error) {
+	var body io.Reader
+	if content != "" {
+		body = strings.NewReader(content)
+	}
+	req, err := http.NewRequest(method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	// PHP sends no User-Agent unless one is configured
+	req.Header.Set("User-Agent", opts.userAgent)
+	for _, line := range opts.headers {
+		name, value, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(name) == "" {
+			continue
+		}
+		req.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	if content != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return client.Do(req)
+}
+
+// isRedirect reports whether a status code redirects to its Location
+func isRedirect(status int) bool {
+	switch status {
+	case 301, 302, 303, 307, 308:
+		return true
+	}
+	return false
+}
+
+// responseHeaderLines returns the status line and headers of a response
+func responseHeaderLines(resp *http.Response) []string {
+	lines := []string{resp.Proto + " " + resp.Status}
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range resp.Header[name] {
+			lines = append(lines, name+": "+value)
+		}
+	}
+	return lines
+}
+
+// httpError returns PHP's description of a failed request
+func httpError(err error, target *url.URL) error {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr):
+		return fmt.Errorf("php_network_getaddresses: getaddrinfo for %s failed: Name or service not known", target.Hostname())
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		if opErr.Timeout() {
+			return errors.New("Connection timed out")
+		}
+		return errors.New("Connection refused")
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return errors.New("HTTP request failed! Connection timed out")
+	}
+	return errors.New("HTTP request failed!")
+}
+
+// ============================================================================
+// Opening URLs
+// ============================================================================
+
+// readOnlyBody adapts a response body to a stream handle
+type readOnlyBody struct {
+	io.ReadCloser
+}
+
+func (readOnlyBody) Write([]byte) (int, error) {
+	return 0, types.ErrStreamNotWritable
+}
+
+// HTTPStream wraps the body of an HTTP response in a read-only stream
+// resource
+func HTTPStream(rawURL string, resp *HTTPResponse) *types.Value {
+	mode, _ := types.ParseStreamMode("r")
+	stream := types.NewStream(readOnlyBody{resp.Body}, rawURL, mode)
+	return types.NewResource(types.NewStreamResource(stream))
+}
+
+// ResponseHeaderArray returns the headers of a response as the
+// $http_response_header array
+func ResponseHeaderArray(resp *HTTPResponse) *types.Value {
+	headers := types.NewEmptyArray()
+	if resp != nil {
+		for _, line := range resp.Headers {
+			headers.Append(types.NewString(line))
+		}
+	}
+	return types.NewArray(headers)
+}
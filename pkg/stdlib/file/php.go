@@ -0,0 +1,157 @@
+package file
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Opening Streams
+// ============================================================================
+
+// OpenStream opens a local file or a php:// stream with an fopen() mode.
+// The error's message is what PHP reports after "Failed to open stream: ".
+func OpenStream(filename string, mode string) (*types.Stream, error) {
+	if IsPHPURL(filename) {
+		return openPHPStream(filename, mode)
+	}
+	stream, err := types.OpenFileStream(filename, mode)
+	if err != nil {
+		return nil, openError(err)
+	}
+	return stream, nil
+}
+
+// openError returns the error of a failed open with the message of its
+// system error, capitalized as in PHP ("No such file or directory")
+func openError(err error) error {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return err
+	}
+	message := errno.Error()
+	return errors.New(strings.ToUpper(message[:1]) + message[1:])
+}
+
+// ============================================================================
+// php:// Streams
+// ============================================================================
+
+// IsPHPURL reports whether a filename is opened by the php:// wrapper
+func IsPHPURL(filename string) bool {
+	return strings.HasPrefix(strings.ToLower(filename), "php://")
+}
+
+// openPHPStream opens php://memory, php://temp (kept in memory whatever
+// its maxmemory) and the standard streams of the process. php://output
+// and the VM's own php://stdout are opened by the VM, which writes them
+// to the script output.
+func openPHPStream(filename string, mode string) (*types.Stream, error) {
+	name := strings.ToLower(filename[len("php://"):])
+	switch {
+	case name == "memory":
+		return types.NewStream(&memoryHandle{}, filename, memoryMode(mode)), nil
+	case name == "temp" || strings.HasPrefix(name, "temp/"):
+		return types.NewStream(&memoryHandle{temp: true}, filename, memoryMode(mode)), nil
+	case name == "stdin":
+		return NewStdioStream(filename, mode, os.Stdin, nil), nil
+	case name == "stdout":
+		return NewStdioStream(filename, mode, nil, os.Stdout), nil
+	case name == "stderr":
+		return NewStdioStream(filename, mode, nil, os.Stderr), nil
+	}
+	return nil, errors.New("operation failed")
+}
+
+// memoryMode returns the mode of a memory stream: always readable, and
+// writable unless opened read-only ("r", "rb")
+func memoryMode(mode string) types.StreamMode {
+	return types.StreamMode{
+		Mode:   mode,
+		Read:   true,
+		Write:  strings.ContainsAny(mode, "wa+"),
+		Append: strings.Contains(mode, "a"),
+	}
+}
+
+// memoryHandle is the handle of php://memory and php://temp streams
+type memoryHandle struct {
+	data []byte
+	pos  int64
+	temp bool
+}
+
+func (h *memoryHandle) Read(p []byte) (int, error) {
+	if h.pos >= int64(len(h.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, h.data[h.pos:])
+	h.pos += int64(n)
+	return n, nil
+}
+
+// Write writes at the position, extending the data as needed
+func (h *memoryHandle) Write(p []byte) (int, error) {
+	end := h.pos + int64(len(p))
+	if end > int64(len(h.data)) {
+		h.data = append(h.data, make([]byte, end-int64(len(h.data)))...)
+	}
+	copy(h.data[h.pos:], p)
+	h.pos = end
+	return len(p), nil
+}
+
+// Seek moves the position within the data; like PHP, memory streams
+// cannot seek past their end
+func (h *memoryHandle) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += h.pos
+	case io.SeekEnd:
+		offset += int64(len(h.data))
+	}
+	if offset < 0 || offset > int64(len(h.data)) {
+		return h.pos, errors.New("invalid offset")
+	}
+	h.pos = offset
+	return offset, nil
+}
+
+func (h *memoryHandle) Close() error {
+	h.data = nil
+	return nil
+}
+
+// stdioHandle is the handle of a standard stream of the process. Closing
+// it leaves the stream itself open, as PHP duplicates the descriptor.
+type stdioHandle struct {
+	r io.Reader
+	w io.Writer
+}
+
+func (h stdioHandle) Read(p []byte) (int, error) {
+	if h.r == nil {
+		return 0, io.EOF
+	}
+	return h.r.Read(p)
+}
+
+func (h stdioHandle) Write(p []byte) (int, error) {
+	if h.w == nil {
+		return 0, types.ErrStreamNotWritable
+	}
+	return h.w.Write(p)
+}
+
+func (stdioHandle) Close() error { return nil }
+
+// NewStdioStream returns a standard stream reading from r or writing to
+// w. Reads return the data available, as from a pipe.
+func NewStdioStream(uri string, mode string, r io.Reader, w io.Writer) *types.Stream {
+	return types.NewPipeStream(stdioHandle{r: r, w: w}, uri, types.StreamMode{Mode: mode, Read: r != nil, Write: w != nil})
+}
@@ -0,0 +1,672 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Constants
+// ============================================================================
+
+// Flags of stream_socket_client(), stream_socket_server(),
+// stream_socket_shutdown() and stream_socket_recvfrom()
+const (
+	STREAM_CLIENT_PERSISTENT    = 1
+	STREAM_CLIENT_ASYNC_CONNECT = 2
+	STREAM_CLIENT_CONNECT       = 4
+	STREAM_SERVER_BIND          = 4
+	STREAM_SERVER_LISTEN        = 8
+	STREAM_SHUT_RD              = 0
+	STREAM_SHUT_WR              = 1
+	STREAM_SHUT_RDWR            = 2
+	STREAM_OOB                  = 1
+	STREAM_PEEK                 = 2
+)
+
+// Constants returns the file, stream and socket constants
+func Constants() map[string]*types.Value {
+	constants := map[string]int64{
+		"SEEK_SET": SEEK_SET,
+		"SEEK_CUR": SEEK_CUR,
+		"SEEK_END": SEEK_END,
+
+		"LOCK_SH": LOCK_SH,
+		"LOCK_EX": LOCK_EX,
+		"LOCK_UN": LOCK_UN,
+		"LOCK_NB": LOCK_NB,
+
+		"FILE_USE_INCLUDE_PATH":   FILE_USE_INCLUDE_PATH,
+		"FILE_IGNORE_NEW_LINES":   FILE_IGNORE_NEW_LINES,
+		"FILE_SKIP_EMPTY_LINES":   FILE_SKIP_EMPTY_LINES,
+		"FILE_APPEND":             FILE_APPEND,
+		"FILE_NO_DEFAULT_CONTEXT": FILE_NO_DEFAULT_CONTEXT,
+
+		"STREAM_CLIENT_PERSISTENT":    STREAM_CLIENT_PERSISTENT,
+		"STREAM_CLIENT_ASYNC_CONNECT": STREAM_CLIENT_ASYNC_CONNECT,
+		"STREAM_CLIENT_CONNECT":       STREAM_CLIENT_CONNECT,
+		"STREAM_SERVER_BIND":          STREAM_SERVER_BIND,
+		"STREAM_SERVER_LISTEN":        STREAM_SERVER_LISTEN,
+		"STREAM_SHUT_RD":              STREAM_SHUT_RD,
+		"STREAM_SHUT_WR":              STREAM_SHUT_WR,
+		"STREAM_SHUT_RDWR":            STREAM_SHUT_RDWR,
+		"STREAM_OOB":                  STREAM_OOB,
+		"STREAM_PEEK":                 STREAM_PEEK,
+	}
+	for name, value := range cryptoMethods {
+		constants[name] = value
+	}
+	result := make(map[string]*types.Value, len(constants))
+	for name, value := range constants {
+		result[name] = types.NewInt(value)
+	}
+	return result
+}
+
+// ============================================================================
+// Socket Handles
+// ============================================================================
+
+// errNotConnected is returned by reads and writes of sockets without a peer
+var errNotConnected = errors.New("socket is not connected")
+
+// socketConn is the handle of a connected socket stream
+type socketConn struct {
+	net.Conn
+	network string
+	host    string // Name of the peer, checked against its certificate
+	ctx     *StreamContext
+	server  bool // Accepted by a server socket
+	raw     net.Conn
+	secure  bool
+}
+
+// packetHandle is the handle of a bound UDP or unixgram server socket.
+// Writes go to the peer of the last datagram received.
+type packetHandle struct {
+	net.PacketConn
+	network string
+	peer    net.Addr
+}
+
+func (p *packetHandle) Read(b []byte) (int, error) {
+	n, addr, err := p.ReadFrom(b)
+	if addr != nil {
+		p.peer = addr
+	}
+	return n, err
+}
+
+func (p *packetHandle) Write(b []byte) (int, error) {
+	if p.peer == nil {
+		return 0, errNotConnected
+	}
+	return p.WriteTo(b, p.peer)
+}
+
+// serverHandle is the handle of a listening server socket. stream_select()
+// accepts connections ahead of stream_socket_accept() into pending.
+type serverHandle struct {
+	listener net.Listener
+	network  string
+	ctx      *StreamContext
+	secure   bool
+	pending  net.Conn
+}
+
+func (s *serverHandle) Read([]byte) (int, error)  { return 0, errNotConnected }
+func (s *serverHandle) Write([]byte) (int, error) { return 0, errNotConnected }
+func (s *serverHandle) Close() error              { return s.listener.Close() }
+
+// setDeadline sets the deadline of Accept
+func (s *serverHandle) setDeadline(deadline time.Time) {
+	if l, ok := s.listener.(interface{ SetDeadline(time.Time) error }); ok {
+		l.SetDeadline(deadline)
+	}
+}
+
+// accept waits for a connection until the deadline; a zero deadline waits
+// indefinitely
+func (s *serverHandle) accept(deadline time.Time) (net.Conn, error) {
+	if conn := s.pending; conn != nil {
+		s.pending = nil
+		return conn, nil
+	}
+	s.setDeadline(deadline)
+	defer s.setDeadline(time.Time{})
+	return s.listener.Accept()
+}
+
+// socketStream wraps a connection in a stream resource
+func socketStream(conn *socketConn) *types.Value {
+	uri := conn.network + "://" + conn.RemoteAddr().String()
+	return types.NewResource(types.NewStreamResource(types.NewSocketStream(conn, uri)))
+}
+
+// socketOf returns the stream and handle of a socket resource
+func socketOf(value *types.Value) (*types.Stream, any, bool) {
+	s, ok := streamOf(value)
+	if !ok || !s.IsSocket() {
+		return nil, nil, false
+	}
+	return s, s.Handle(), true
+}
+
+// ============================================================================
+// Addresses and Errors
+// ============================================================================
+
+// SocketError is the failure of a connection or bind, reported through
+// the $error_code and $error_message arguments
+type SocketError struct {
+	Code    int
+	Message string
+}
+
+func (e *SocketError) Error() string {
+	return e.Message
+}
+
+// newSocketError describes a network error the way the C library does
+func newSocketError(err error, host string) *SocketError {
+	var errno syscall.Errno
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr):
+		return &SocketError{0, fmt.Sprintf("php_network_getaddresses: getaddrinfo for %s failed: Name or service not known", host)}
+	case errors.As(err, &errno):
+		message := errno.Error()
+		return &SocketError{int(errno), strings.ToUpper(message[:1]) + message[1:]}
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return &SocketError{int(syscall.ETIMEDOUT), "Connection timed out"}
+	}
+	return &SocketError{0, err.Error()}
+}
+
+// socketAddress is a parsed "transport://target" address
+type socketAddress struct {
+	transport string // tcp, udp, unix, udg, ssl or tls
+	target    string // host:port or socket path
+	host      string
+}
+
+// network returns the Go network of the transport
+func (a socketAddress) network() string {
+	switch a.transport {
+	case "udp":
+		return "udp"
+	case "unix":
+		return "unix"
+	case "udg":
+		return "unixgram"
+	}
+	return "tcp"
+}
+
+// secure reports whether the transport negotiates TLS on connect
+func (a socketAddress) secure() bool {
+	return a.transport == "ssl" || a.transport == "tls" || strings.HasPrefix(a.transport, "tlsv")
+}
+
+// parseSocketAddress splits an address such as "tcp://127.0.0.1:80"; the
+// transport defaults to tcp. A non-negative port is appended to the host,
+// as fsockopen() does.
+func parseSocketAddress(address string, port int64) (socketAddress, error) {
+	addr := socketAddress{transport: "tcp", target: address}
+	if transport, target, found := strings.Cut(address, "://"); found {
+		addr.transport, addr.target = strings.ToLower(transport), target
+	}
+	switch addr.transport {
+	case "tcp", "udp", "ssl", "tls", "tlsv1.0", "tlsv1.1", "tlsv1.2", "tlsv1.3":
+	case "unix", "udg":
+		return addr, nil
+	default:
+		return addr, fmt.Errorf("Unable to find the socket transport \"%s\" - did you forget to enable it when you configured PHP?", addr.transport)
+	}
+
+	if port >= 0 {
+		host := addr.target
+		if strings.Contains(host, ":") && !strings.HasPrefix(host, "[") {
+			host = "[" + host + "]"
+		}
+		addr.target = host + ":" + strconv.FormatInt(port, 10)
+	}
+	host, _, err := net.SplitHostPort(addr.target)
+	if err != nil {
+		return addr, fmt.Errorf("Failed to parse address \"%s\"", addr.target)
+	}
+	addr.host = host
+	return addr, nil
+}
+
+// ============================================================================
+// Clients and Servers
+// ============================================================================
+
+// SocketClient connects to an address, as stream_socket_client() and
+// fsockopen() do; port is -1 when the address includes it
+func SocketClient(address string, port int64, timeout time.Duration, ctx *StreamContext) (*types.Value, *SocketError) {
+	addr, err := parseSocketAddress(address, port)
+	if err != nil {
+		return nil, &SocketError{0, err.Error()}
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.Dial(addr.network(), addr.target)
+	if err != nil {
+		return nil, newSocketError(err, addr.host)
+	}
+
+	sc := &socketConn{Conn: conn, network: addr.network(), host: addr.host, ctx: ctx, raw: conn}
+	if addr.secure() {
+		if err := sc.enableCrypto(false, versionsOf(addr.transport), timeout); err != nil {
+			conn.Close()
+			return nil, &SocketError{0, err.Error()}
+		}
+	}
+	return socketStream(sc), nil
+}
+
+// SocketServer creates a server socket, as stream_socket_server() does.
+// Datagram transports are only bound.
+func SocketServer(address string, flags int64, ctx *StreamContext) (*types.Value, *SocketError) {
+	addr, err := parseSocketAddress(address, -1)
+	if err != nil {
+		return nil, &SocketError{0, err.Error()}
+	}
+	network := addr.network()
+	if network == "udp" || network == "unixgram" {
+		conn, err := net.ListenPacket(network, addr.target)
+		if err != nil {
+			return nil, newSocketError(err, addr.host)
+		}
+		handle := &packetHandle{PacketConn: conn, network: network}
+		return types.NewResource(types.NewStreamResource(types.NewSocketStream(handle, addr.transport+"://"+addr.target))), nil
+	}
+	if flags&STREAM_SERVER_LISTEN == 0 {
+		return nil, &SocketError{0, "Binding a stream socket without listening is not supported"}
+	}
+
+	listener, err := net.Listen(network, addr.target)
+	if err != nil {
+		return nil, newSocketError(err, addr.host)
+	}
+	handle := &serverHandle{listener: listener, network: network, ctx: ctx, secure: addr.secure()}
+	return types.NewResource(types.NewStreamResource(types.NewSocketStream(handle, addr.transport+"://"+addr.target))), nil
+}
+
+// SocketAccept accepts a connection on a server socket, as
+// stream_socket_accept() does, waiting at most timeout (negative waits
+// indefinitely). It returns the connection and the peer's address.
+func SocketAccept(server *types.Value, timeout time.Duration) (*types.Value, string, error) {
+	_, handle, ok := socketOf(server)
+	listener, isServer := handle.(*serverHandle)
+	if !ok || !isServer {
+		return nil, "", errors.New("Accept failed: Operation not supported")
+	}
+	deadline := time.Time{}
+	if timeout >= 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	conn, err := listener.accept(deadline)
+	if err != nil {
+		return nil, "", errors.New("Accept failed: " + newSocketError(err, "").Message)
+	}
+
+	sc := &socketConn{Conn: conn, network: listener.network, ctx: listener.ctx, server: true, raw: conn}
+	if listener.secure {
+		if err := sc.enableCrypto(true, nil, timeout); err != nil {
+			conn.Close()
+			return nil, "", err
+		}
+	}
+	return socketStream(sc), conn.RemoteAddr().String(), nil
+}
+
+// SocketGetName returns the local or remote address of a socket
+// stream_socket_get_name(resource $socket, bool $remote): string|false
+func SocketGetName(socket *types.Value, remote *types.Value) *types.Value {
+	_, handle, ok := socketOf(socket)
+	if !ok {
+		return types.NewBool(false)
+	}
+	var addr net.Addr
+	switch h := handle.(type) {
+	case *socketConn:
+		addr = h.LocalAddr()
+		if remote.ToBool() {
+			addr = h.RemoteAddr()
+		}
+	case *packetHandle:
+		addr = h.LocalAddr()
+		if remote.ToBool() {
+			addr = h.peer
+		}
+	case *serverHandle:
+		if !remote.ToBool() {
+			addr = h.listener.Addr()
+		}
+	}
+	if addr == nil || addr.String() == "" {
+		return types.NewBool(false)
+	}
+	return types.NewString(addr.String())
+}
+
+// SocketShutdown shuts down reception, transmission or both of a
+// full-duplex connection
+// stream_socket_shutdown(resource $stream, int $mode): bool
+func SocketShutdown(stream *types.Value, how *types.Value) *types.Value {
+	_, handle, ok := socketOf(stream)
+	conn, isConn := handle.(*socketConn)
+	if !ok || !isConn {
+		return types.NewBool(false)
+	}
+	mode := how.ToInt()
+	var err error
+	if mode == STREAM_SHUT_RD || mode == STREAM_SHUT_RDWR {
+		if c, ok := conn.Conn.(interface{ CloseRead() error }); ok {
+			err = c.CloseRead()
+		}
+	}
+	if mode == STREAM_SHUT_WR || mode == STREAM_SHUT_RDWR {
+		if c, ok := conn.Conn.(interface{ CloseWrite() error }); ok {
+			err = errors.Join(err, c.CloseWrite())
+		}
+	}
+	return types.NewBool(err == nil)
+}
+
+// SocketRecvFrom receives data from a socket, connected or not, returning
+// it with the sender's address; STREAM_PEEK leaves the data to be read
+// again
+// stream_socket_recvfrom(resource $socket, int $length, int $flags = 0, ?string &$address = null): string|false
+func SocketRecvFrom(socket *types.Value, length *types.Value, flags *types.Value) (*types.Value, string) {
+	s, handle, ok := socketOf(socket)
+	if !ok || length.ToInt() <= 0 {
+		return types.NewBool(false), ""
+	}
+	var data []byte
+	var err error
+	if flags.ToInt()&STREAM_PEEK != 0 {
+		data, err = s.Peek(int(length.ToInt()))
+	} else {
+		data, err = s.Read(int(length.ToInt()))
+	}
+	if err != nil {
+		return types.NewBool(false), ""
+	}
+	address := ""
+	switch h := handle.(type) {
+	case *packetHandle:
+		if h.peer != nil {
+			address = h.peer.String()
+		}
+	case *socketConn:
+		address = h.RemoteAddr().String()
+	}
+	return types.NewString(string(data)), address
+}
+
+// SocketSendTo sends data to a socket, to address if it is not empty
+// stream_socket_sendto(resource $socket, string $data, int $flags = 0, string $address = ""): int|false
+func SocketSendTo(socket *types.Value, data *types.Value, address string) *types.Value {
+	s, handle, ok := socketOf(socket)
+	if !ok {
+		return types.NewBool(false)
+	}
+	payload := []byte(data.ToString())
+	if packet, isPacket := handle.(*packetHandle); isPacket && address != "" {
+		target, err := parseSocketAddress(address, -1)
+		if err != nil {
+			return types.NewBool(false)
+		}
+		var to net.Addr
+		if packet.network == "unixgram" {
+			to, err = net.ResolveUnixAddr("unixgram", target.target)
+		} else {
+			to, err = net.ResolveUDPAddr("udp", target.target)
+		}
+		if err != nil {
+			return types.NewBool(false)
+		}
+		n, err := packet.WriteTo(payload, to)
+		if err != nil {
+			return types.NewBool(false)
+		}
+		return types.NewInt(int64(n))
+	}
+	n, err := s.Write(payload)
+	if err != nil {
+		return types.NewBool(false)
+	}
+	return types.NewInt(int64(n))
+}
+
+// ============================================================================
+// Blocking, Timeouts and Metadata
+// ============================================================================
+
+// StreamSetBlocking sets blocking or non-blocking mode on a stream
+// stream_set_blocking(resource $stream, bool $enable): bool
+func StreamSetBlocking(stream *types.Value, enable *types.Value) *types.Value {
+	s, ok := streamOf(stream)
+	if !ok {
+		return types.NewBool(false)
+	}
+	return types.NewBool(s.SetBlocking(enable.ToBool()))
+}
+
+// StreamSetTimeout sets the read timeout of a socket
+// stream_set_timeout(resource $stream, int $seconds, int $microseconds = 0): bool
+func StreamSetTimeout(stream *types.Value, seconds *types.Value, args ...*types.Value) *types.Value {
+	s, ok := streamOf(stream)
+	if !ok {
+		return types.NewBool(false)
+	}
+	timeout := time.Duration(seconds.ToInt()) * time.Second
+	if len(args) > 0 {
+		timeout += time.Duration(args[0].ToInt()) * time.Microsecond
+	}
+	return types.NewBool(s.SetTimeout(timeout))
+}
+
+// StreamGetMetaData returns information about a stream
+// stream_get_meta_data(resource $stream): array
+func StreamGetMetaData(stream *types.Value) *types.Value {
+	s, ok := streamOf(stream)
+	if !ok {
+		return types.NewBool(false)
+	}
+	meta := types.NewEmptyArray()
+	set := func(key string, value *types.Value) { meta.Set(types.NewString(key), value) }
+	set("timed_out", types.NewBool(s.TimedOut()))
+	set("blocked", types.NewBool(s.Blocking() || !s.IsSocket()))
+	set("eof", types.NewBool(s.EOF()))
+
+	_, seekable := s.Handle().(interface {
+		Seek(int64, int) (int64, error)
+	})
+	switch h := s.Handle().(type) {
+	case *socketConn:
+		streamType := h.network + "_socket"
+		if h.secure {
+			streamType = "tcp_socket/ssl"
+		}
+		set("stream_type", types.NewString(streamType))
+	case *packetHandle:
+		set("stream_type", types.NewString(strings.TrimSuffix(h.network, "gram")+"_socket"))
+	case *serverHandle:
+		set("stream_type", types.NewString(h.network+"_socket"))
+	case readOnlyBody:
+		set("wrapper_type", types.NewString("http"))
+		set("stream_type", types.NewString("tcp_socket"))
+	case *memoryHandle:
+		set("wrapper_type", types.NewString("PHP"))
+		if h.temp {
+			set("stream_type", types.NewString("TEMP"))
+		} else {
+			set("stream_type", types.NewString("MEMORY"))
+		}
+	case stdioHandle:
+		set("wrapper_type", types.NewString("PHP"))
+		set("stream_type", types.NewString("STDIO"))
+	default:
+		// Pipes have no wrapper
+		if !s.IsSocket() {
+			set("wrapper_type", types.NewString("plainfile"))
+		}
+		set("stream_type", types.NewString("STDIO"))
+	}
+	set("mode", types.NewString(s.Mode().Mode))
+	set("unread_bytes", types.NewInt(int64(s.Buffered())))
+	set("seekable", types.NewBool(seekable))
+	if _, stdio := s.Handle().(stdioHandle); stdio || !s.IsSocket() {
+		set("uri", types.NewString(s.URI()))
+	}
+	return types.NewArray(meta)
+}
+
+// ============================================================================
+// stream_select()
+// ============================================================================
+
+// Select waits until a stream of read has data, accepts a connection or
+// reaches its end, or a stream of write can be written, for at most
+// timeout (negative waits indefinitely). It returns the ready streams
+// under their keys; invalid entries are dropped.
+func Select(read, write *types.Array, timeout time.Duration) (*types.Array, *types.Array) {
+	// Connected sockets and files are always writable
+	readyWrite := types.NewEmptyArray()
+	if write != nil {
+		write.Each(func(key, value *types.Value) bool {
+			if _, ok := streamOf(value); ok {
+				readyWrite.Set(key, value)
+			}
+			return true
+		})
+	}
+
+	readyRead := types.NewEmptyArray()
+	if read == nil {
+		return readyRead, readyWrite
+	}
+	var waiting []selectWaiter
+	read.Each(func(key, value *types.Value) bool {
+		s, ok := streamOf(value)
+		if !ok {
+			return true
+		}
+		w := selectWaiter{key: key, value: value, stream: s}
+		if w.immediate() {
+			readyRead.Set(key, value)
+		} else {
+			waiting = append(waiting, w)
+		}
+		return true
+	})
+	if len(waiting) == 0 {
+		return readyRead, readyWrite
+	}
+
+	// With streams already ready, the others are only polled
+	if readyRead.Len() > 0 || readyWrite.Len() > 0 {
+		timeout = time.Millisecond
+	}
+	deadline := time.Time{}
+	if timeout >= 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	// Each stream waits in its own goroutine; the first ready stream
+	// interrupts the others, which may still report data arriving meanwhile
+	done := make(chan int, len(waiting))
+	ready := make([]bool, len(waiting))
+	for i := range waiting {
+		go func(i int) {
+			ready[i] = waiting[i].ready(deadline)
+			done <- i
+		}(i)
+	}
+	finished := make(map[int]bool, len(waiting))
+	first := <-done
+	finished[first] = true
+	for len(finished) < len(waiting) {
+		for i := range waiting {
+			if !finished[i] {
+				waiting[i].interrupt()
+			}
+		}
+		select {
+		case i := <-done:
+			finished[i] = true
+		case <-time.After(time.Millisecond):
+		}
+	}
+	for i, w := range waiting {
+		if ready[i] {
+			readyRead.Set(w.key, w.value)
+		}
+	}
+	return readyRead, readyWrite
+}
+
+// selectWaiter waits for one stream of stream_select()
+type selectWaiter struct {
+	key    *types.Value
+	value  *types.Value
+	stream *types.Stream
+}
+
+// immediate reports whether the stream is readable without waiting
+func (w selectWaiter) immediate() bool {
+	if server, ok := w.stream.Handle().(*serverHandle); ok {
+		return server.pending != nil
+	}
+	return !w.stream.IsSocket() || w.stream.Buffered() > 0 || w.stream.EOF()
+}
+
+// ready waits until the stream is readable or the deadline passes
+func (w selectWaiter) ready(deadline time.Time) bool {
+	server, ok := w.stream.Handle().(*serverHandle)
+	if !ok {
+		return w.stream.WaitReadable(deadline)
+	}
+	if server.pending != nil {
+		return true
+	}
+	server.setDeadline(deadline)
+	defer server.setDeadline(time.Time{})
+	conn, err := server.listener.Accept()
+	if err != nil {
+		return false
+	}
+	server.pending = conn
+	return true
+}
+
+// interrupt makes a wait in progress return
+func (w selectWaiter) interrupt() {
+	if server, ok := w.stream.Handle().(*serverHandle); ok {
+		server.setDeadline(time.Now())
+		return
+	}
+	w.stream.Interrupt()
+}
+
+// withTimeout returns a context ending after timeout; negative timeouts
+// never end
+func withTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout < 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
@@ -0,0 +1,273 @@
+package file
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// listen starts a TCP server socket on a free local port
+func listen(t *testing.T, transport string, ctx *StreamContext) (*types.Value, string) {
+	t.Helper()
+	server, err := SocketServer(transport+"://127.0.0.1:0", STREAM_SERVER_BIND|STREAM_SERVER_LISTEN, ctx)
+	if err != nil {
+		t.Fatalf("SocketServer: %v", err)
+	}
+	t.Cleanup(func() { Fclose(server) })
+	address := SocketGetName(server, types.NewBool(false)).ToString()
+	return server, address
+}
+
+func TestSocketClientServer(t *testing.T) {
+	server, address := listen(t, "tcp", nil)
+
+	client, err := SocketClient("tcp://"+address, -1, time.Second, nil)
+	if err != nil {
+		t.Fatalf("SocketClient: %v", err)
+	}
+	defer Fclose(client)
+	conn, peer, acceptErr := SocketAccept(server, time.Second)
+	if acceptErr != nil || peer != SocketGetName(client, types.NewBool(false)).ToString() {
+		t.Fatalf("SocketAccept = %v, %q, %v", conn, peer, acceptErr)
+	}
+	defer Fclose(conn)
+
+	Fwrite(client, types.NewString("hello\nworld"))
+	if line := Fgets(conn); line.ToString() != "hello\n" {
+		t.Errorf("Fgets = %v", line)
+	}
+	// Socket reads return the data available
+	if data := Fread(conn, types.NewInt(100)); data.ToString() != "world" {
+		t.Errorf("Fread = %v", data)
+	}
+
+	SocketShutdown(client, types.NewInt(STREAM_SHUT_WR))
+	if data := Fread(conn, types.NewInt(10)); data.ToString() != "" || !Feof(conn).ToBool() {
+		t.Errorf("after shutdown: %v, eof %v", data, Feof(conn))
+	}
+}
+
+func TestSocketTimeoutAndNonBlocking(t *testing.T) {
+	server, address := listen(t, "tcp", nil)
+	client, _ := SocketClient(address, -1, time.Second, nil)
+	defer Fclose(client)
+	conn, _, _ := SocketAccept(server, time.Second)
+	defer Fclose(conn)
+
+	StreamSetTimeout(client, types.NewInt(0), types.NewInt(20000))
+	start := time.Now()
+	if data := Fread(client, types.NewInt(10)); data.ToString() != "" {
+		t.Errorf("Fread = %v", data)
+	}
+	meta := StreamGetMetaData(client).ToArray()
+	if timedOut, _ := meta.Get(types.NewString("timed_out")); !timedOut.ToBool() || time.Since(start) > time.Second {
+		t.Errorf("meta = %v after %v", meta, time.Since(start))
+	}
+	if streamType, _ := meta.Get(types.NewString("stream_type")); streamType.ToString() != "tcp_socket" {
+		t.Errorf("stream_type = %v", streamType)
+	}
+
+	StreamSetBlocking(client, types.NewBool(false))
+	if line := Fgets(client); line.ToBool() {
+		t.Errorf("non-blocking Fgets without data = %v", line)
+	}
+	meta = StreamGetMetaData(client).ToArray()
+	if blocked, _ := meta.Get(types.NewString("blocked")); blocked.ToBool() {
+		t.Errorf("blocked = %v", blocked)
+	}
+	Fwrite(conn, types.NewString("partial"))
+	time.Sleep(20 * time.Millisecond)
+	if line := Fgets(client); line.ToBool() {
+		t.Errorf("an incomplete line is kept: %v", line)
+	}
+	Fwrite(conn, types.NewString(" line\n"))
+	time.Sleep(20 * time.Millisecond)
+	if line := Fgets(client); line.ToString() != "partial line\n" {
+		t.Errorf("Fgets = %v", line)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	server, address := listen(t, "tcp", nil)
+	servers := types.NewArrayFromMap(map[interface{}]*types.Value{"server": server})
+
+	start := time.Now()
+	read, _ := Select(servers, nil, 30*time.Millisecond)
+	if read.Len() != 0 || time.Since(start) < 25*time.Millisecond {
+		t.Errorf("Select without connections = %v after %v", read, time.Since(start))
+	}
+
+	client, _ := SocketClient(address, -1, time.Second, nil)
+	defer Fclose(client)
+	read, _ = Select(servers, nil, time.Second)
+	if _, ok := read.Get(types.NewString("server")); !ok {
+		t.Fatalf("a pending connection makes the server readable")
+	}
+	conn, _, err := SocketAccept(server, 0)
+	if err != nil {
+		t.Fatalf("the connection accepted by Select is returned: %v", err)
+	}
+	defer Fclose(conn)
+
+	streams := types.NewEmptyArray()
+	streams.Append(conn)
+	streams.Append(client)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		Fwrite(client, types.NewString("ping"))
+	}()
+	read, write := Select(streams, streams, -1)
+	if write.Len() != 2 {
+		t.Errorf("connected sockets are writable: %v", write)
+	}
+	read, _ = Select(streams, nil, time.Second)
+	if read.Len() != 1 {
+		t.Fatalf("Select = %v", read)
+	}
+	if value, ok := read.Get(types.NewInt(0)); !ok || value != conn {
+		t.Errorf("the key of the ready stream is kept: %v", read)
+	}
+	if data := Fread(conn, types.NewInt(10)); data.ToString() != "ping" {
+		t.Errorf("data buffered by Select is read: %v", data)
+	}
+}
+
+func TestSocketUDP(t *testing.T) {
+	server, err := SocketServer("udp://127.0.0.1:0", STREAM_SERVER_BIND, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Fclose(server)
+	address := SocketGetName(server, types.NewBool(false)).ToString()
+
+	client, err := SocketClient("udp://"+address, -1, time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Fclose(client)
+	SocketSendTo(client, types.NewString("datagram"), "")
+
+	data, from := SocketRecvFrom(server, types.NewInt(100), types.NewInt(0))
+	if data.ToString() != "datagram" || from != SocketGetName(client, types.NewBool(false)).ToString() {
+		t.Fatalf("SocketRecvFrom = %v from %q", data, from)
+	}
+	SocketSendTo(server, types.NewString("reply"), "udp://"+from)
+	if data := Fread(client, types.NewInt(100)); data.ToString() != "reply" {
+		t.Errorf("reply = %v", data)
+	}
+}
+
+func TestSocketErrors(t *testing.T) {
+	probe, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed := probe.Addr().String()
+	probe.Close()
+
+	if _, err := SocketClient(closed, -1, time.Second, nil); err == nil || err.Code != int(syscall.ECONNREFUSED) || err.Message != "Connection refused" {
+		t.Errorf("connecting to a closed port: %+v", err)
+	}
+	if _, err := SocketClient("bogus://x:1", -1, time.Second, nil); err == nil || !strings.HasPrefix(err.Message, `Unable to find the socket transport "bogus"`) {
+		t.Errorf("unknown transport: %+v", err)
+	}
+	if _, err := SocketClient("127.0.0.1", -1, time.Second, nil); err == nil || err.Message != `Failed to parse address "127.0.0.1"` {
+		t.Errorf("missing port: %+v", err)
+	}
+
+	_, address := listen(t, "tcp", nil)
+	if _, err := SocketServer("tcp://"+address, STREAM_SERVER_BIND|STREAM_SERVER_LISTEN, nil); err == nil || err.Code != int(syscall.EADDRINUSE) {
+		t.Errorf("binding a used port: %+v", err)
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate for localhost and
+// its key to a PEM file
+func writeTestCertificate(t *testing.T) string {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	data := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})...)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func sslContext(options map[interface{}]*types.Value) *StreamContext {
+	ctx, _ := ContextOf(StreamContextCreate(types.NewArray(types.NewArrayFromMap(map[interface{}]*types.Value{
+		"ssl": types.NewArray(types.NewArrayFromMap(options)),
+	}))))
+	return ctx
+}
+
+func TestSocketTLS(t *testing.T) {
+	cert := writeTestCertificate(t)
+	server, address := listen(t, "tls", sslContext(map[interface{}]*types.Value{"local_cert": types.NewString(cert)}))
+	_, port, _ := net.SplitHostPort(address)
+
+	accepted := make(chan *types.Value, 1)
+	go func() {
+		// The handshake fails on the server side too
+		conn, _, _ := SocketAccept(server, time.Second)
+		accepted <- conn
+	}()
+
+	// The self-signed certificate is rejected unless allowed
+	if _, err := SocketClient("tls://localhost:"+port, -1, time.Second, nil); err == nil || !strings.HasPrefix(err.Message, "SSL operation failed") {
+		t.Errorf("an untrusted certificate is rejected: %+v", err)
+	}
+	<-accepted
+
+	go func() {
+		conn, _, _ := SocketAccept(server, time.Second)
+		accepted <- conn
+	}()
+	ctx := sslContext(map[interface{}]*types.Value{"allow_self_signed": types.NewBool(true)})
+	client, err := SocketClient("tcp://localhost:"+port, -1, time.Second, ctx)
+	if err != nil {
+		t.Fatalf("SocketClient: %v", err)
+	}
+	defer Fclose(client)
+	if result, err := EnableCrypto(client, true, types.NewInt(cryptoMethods["STREAM_CRYPTO_METHOD_TLS_CLIENT"])); !result.ToBool() || err != nil {
+		t.Fatalf("EnableCrypto = %v, %v", result, err)
+	}
+	conn := <-accepted
+	defer Fclose(conn)
+
+	Fwrite(client, types.NewString("secret\n"))
+	if line := Fgets(conn); line.ToString() != "secret\n" {
+		t.Errorf("Fgets over TLS = %v", line)
+	}
+	meta := StreamGetMetaData(client).ToArray()
+	if streamType, _ := meta.Get(types.NewString("stream_type")); streamType.ToString() != "tcp_socket/ssl" {
+		t.Errorf("stream_type = %v", streamType)
+	}
+}
@@ -0,0 +1,220 @@
+package file
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// TLS
+// ============================================================================
+
+// cryptoMethods are the STREAM_CRYPTO_METHOD_* constants. Client methods
+// have the lowest bit set; the others are bits of protocol versions.
+var cryptoMethods = map[string]int64{
+	"STREAM_CRYPTO_METHOD_SSLv2_CLIENT":   3,
+	"STREAM_CRYPTO_METHOD_SSLv3_CLIENT":   5,
+	"STREAM_CRYPTO_METHOD_SSLv23_CLIENT":  57,
+	"STREAM_CRYPTO_METHOD_TLS_CLIENT":     121,
+	"STREAM_CRYPTO_METHOD_TLSv1_0_CLIENT": 9,
+	"STREAM_CRYPTO_METHOD_TLSv1_1_CLIENT": 17,
+	"STREAM_CRYPTO_METHOD_TLSv1_2_CLIENT": 33,
+	"STREAM_CRYPTO_METHOD_TLSv1_3_CLIENT": 65,
+	"STREAM_CRYPTO_METHOD_ANY_CLIENT":     127,
+	"STREAM_CRYPTO_METHOD_SSLv2_SERVER":   2,
+	"STREAM_CRYPTO_METHOD_SSLv3_SERVER":   4,
+	"STREAM_CRYPTO_METHOD_SSLv23_SERVER":  120,
+	"STREAM_CRYPTO_METHOD_TLS_SERVER":     120,
+	"STREAM_CRYPTO_METHOD_TLSv1_0_SERVER": 8,
+	"STREAM_CRYPTO_METHOD_TLSv1_1_SERVER": 16,
+	"STREAM_CRYPTO_METHOD_TLSv1_2_SERVER": 32,
+	"STREAM_CRYPTO_METHOD_TLSv1_3_SERVER": 64,
+	"STREAM_CRYPTO_METHOD_ANY_SERVER":     126,
+	"STREAM_CRYPTO_PROTO_TLSv1_0":         8,
+	"STREAM_CRYPTO_PROTO_TLSv1_1":         16,
+	"STREAM_CRYPTO_PROTO_TLSv1_2":         32,
+	"STREAM_CRYPTO_PROTO_TLSv1_3":         64,
+}
+
+// cryptoVersions maps the protocol bits of a crypto method to versions
+var cryptoVersions = []struct {
+	bit     int64
+	version uint16
+}{
+	{8, tls.VersionTLS10},
+	{16, tls.VersionTLS11},
+	{32, tls.VersionTLS12},
+	{64, tls.VersionTLS13},
+}
+
+// versionRange returns the TLS versions enabled by a crypto method; zero
+// leaves the choice to crypto/tls
+func versionRange(method int64) (lowest, highest uint16) {
+	for _, v := range cryptoVersions {
+		if method&v.bit != 0 {
+			if lowest == 0 {
+				lowest = v.version
+			}
+			highest = v.version
+		}
+	}
+	return lowest, highest
+}
+
+// versionsOf returns the crypto method of a transport such as tlsv1.2
+func versionsOf(transport string) *int64 {
+	var method int64
+	switch transport {
+	case "tlsv1.0":
+		method = 9
+	case "tlsv1.1":
+		method = 17
+	case "tlsv1.2":
+		method = 33
+	case "tlsv1.3":
+		method = 65
+	default:
+		return nil
+	}
+	return &method
+}
+
+// tlsConfig builds the configuration of the "ssl" context options:
+// verify_peer, verify_peer_name, allow_self_signed, peer_name, cafile,
+// local_cert and local_pk
+func (c *socketConn) tlsConfig(server bool) (*tls.Config, error) {
+	option := func(name string, fallback bool) bool {
+		if v, ok := c.ctx.Option("ssl", name); ok {
+			return v.ToBool()
+		}
+		return fallback
+	}
+	config := &tls.Config{ServerName: c.host}
+	if v, ok := c.ctx.Option("ssl", "peer_name"); ok {
+		config.ServerName = v.ToString()
+	}
+
+	if v, ok := c.ctx.Option("ssl", "local_cert"); ok {
+		keyFile := v.ToString()
+		if pk, ok := c.ctx.Option("ssl", "local_pk"); ok {
+			keyFile = pk.ToString()
+		}
+		cert, err := tls.LoadX509KeyPair(v.ToString(), keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to set local cert chain file `%s'; Check that your cafile/capath settings include details of your certificate and its issuer", v.ToString())
+		}
+		config.Certificates = []tls.Certificate{cert}
+	} else if server {
+		return nil, errors.New("Unable to set private key file; a server requires the \"local_cert\" ssl context option")
+	}
+	if server {
+		return config, nil
+	}
+
+	// Verification is done here so that the peer name and self-signed
+	// certificates can be handled as PHP does
+	var roots *x509.CertPool
+	if v, ok := c.ctx.Option("ssl", "cafile"); ok {
+		pem, err := os.ReadFile(v.ToString())
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load cafile `%s'", v.ToString())
+		}
+		roots = x509.NewCertPool()
+		roots.AppendCertsFromPEM(pem)
+	}
+	verifyPeer := option("verify_peer", true)
+	verifyName := option("verify_peer_name", true)
+	selfSigned := option("allow_self_signed", false)
+	config.InsecureSkipVerify = true
+	config.VerifyConnection = func(state tls.ConnectionState) error {
+		if !verifyPeer || len(state.PeerCertificates) == 0 {
+			return nil
+		}
+		leaf := state.PeerCertificates[0]
+		opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+		for _, cert := range state.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		if verifyName {
+			opts.DNSName = config.ServerName
+		}
+		_, err := leaf.Verify(opts)
+		if err != nil && selfSigned && len(state.PeerCertificates) == 1 && leaf.CheckSignatureFrom(leaf) == nil {
+			if !verifyName {
+				return nil
+			}
+			err = leaf.VerifyHostname(config.ServerName)
+		}
+		return err
+	}
+	return config, nil
+}
+
+// enableCrypto negotiates TLS over the connection; method is a
+// STREAM_CRYPTO_METHOD_* value or nil for the defaults
+func (c *socketConn) enableCrypto(server bool, method *int64, timeout time.Duration) error {
+	config, err := c.tlsConfig(server)
+	if err != nil {
+		return err
+	}
+	if method != nil {
+		config.MinVersion, config.MaxVersion = versionRange(*method)
+	}
+
+	var conn *tls.Conn
+	if server {
+		conn = tls.Server(c.raw, config)
+	} else {
+		conn = tls.Client(c.raw, config)
+	}
+	ctx, cancel := withTimeout(timeout)
+	defer cancel()
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("SSL operation failed with code 1. %s", err)
+	}
+	c.Conn, c.secure = conn, true
+	return nil
+}
+
+// EnableCrypto turns TLS on or off on a connected socket, as
+// stream_socket_enable_crypto() does. Without a crypto method, accepted
+// connections act as the server.
+func EnableCrypto(stream *types.Value, enable bool, method *types.Value) (*types.Value, error) {
+	_, handle, ok := socketOf(stream)
+	conn, isConn := handle.(*socketConn)
+	if !ok || !isConn {
+		return types.NewBool(false), errors.New("Cannot enable crypto on a stream that is not a connected socket")
+	}
+	if !enable {
+		if conn.secure {
+			conn.Conn.(*tls.Conn).CloseWrite()
+			conn.Conn, conn.secure = conn.raw, false
+		}
+		return types.NewBool(true), nil
+	}
+	if conn.secure {
+		return types.NewBool(true), nil
+	}
+
+	server := conn.server
+	var methodBits *int64
+	if method != nil && !method.IsNull() {
+		bits := method.ToInt()
+		server, methodBits = bits&1 == 0, &bits
+	} else if v, ok := conn.ctx.Option("ssl", "crypto_method"); ok {
+		bits := v.ToInt()
+		methodBits = &bits
+	}
+	// The handshake completes before returning, even on non-blocking
+	// streams
+	if err := conn.enableCrypto(server, methodBits, types.DefaultSocketTimeout); err != nil {
+		return types.NewBool(false), err
+	}
+	return types.NewBool(true), nil
+}
@@ -1,13 +1,9 @@
 package filter
 
 import (
-	"net"
-	"net/mail"
-	"net/url"
-	"regexp"
-	"strconv"
-	"strings"
+	"fmt"
 
+	"github.com/krizos/php-go/pkg/stdlib"
 	"github.com/krizos/php-go/pkg/types"
 )
 
@@ -18,6 +14,7 @@ import (
 const (
 	// Validate filters
 	FILTER_VALIDATE_BOOLEAN = 258
+	FILTER_VALIDATE_BOOL    = 258
 	FILTER_VALIDATE_EMAIL   = 274
 	FILTER_VALIDATE_FLOAT   = 259
 	FILTER_VALIDATE_INT     = 257
@@ -28,21 +25,24 @@ const (
 	FILTER_VALIDATE_DOMAIN  = 277
 
 	// Sanitize filters
-	FILTER_SANITIZE_EMAIL           = 517
-	FILTER_SANITIZE_ENCODED         = 514
-	FILTER_SANITIZE_NUMBER_FLOAT    = 520
-	FILTER_SANITIZE_NUMBER_INT      = 519
-	FILTER_SANITIZE_SPECIAL_CHARS   = 515
-	FILTER_SANITIZE_STRING          = 513
-	FILTER_SANITIZE_STRIPPED        = 513
-	FILTER_SANITIZE_URL             = 518
-	FILTER_SANITIZE_ADD_SLASHES     = 523
+	FILTER_SANITIZE_EMAIL              = 517
+	FILTER_SANITIZE_ENCODED            = 514
+	FILTER_SANITIZE_NUMBER_FLOAT       = 520
+	FILTER_SANITIZE_NUMBER_INT         = 519
+	FILTER_SANITIZE_SPECIAL_CHARS      = 515
+	FILTER_SANITIZE_FULL_SPECIAL_CHARS = 522
+	FILTER_SANITIZE_STRING             = 513
+	FILTER_SANITIZE_STRIPPED           = 513
+	FILTER_SANITIZE_URL                = 518
+	FILTER_SANITIZE_ADD_SLASHES        = 523
 
 	// Other filters
+	FILTER_DEFAULT    = 516
 	FILTER_UNSAFE_RAW = 516
 	FILTER_CALLBACK   = 1024
 
 	// Flags
+	FILTER_FLAG_NONE              = 0
 	FILTER_FLAG_ALLOW_OCTAL       = 1
 	FILTER_FLAG_ALLOW_HEX         = 2
 	FILTER_FLAG_STRIP_LOW         = 4
@@ -62,383 +62,513 @@ const (
 	FILTER_FLAG_IPV6          = 2097152
 	FILTER_FLAG_NO_PRIV_RANGE = 8388608
 	FILTER_FLAG_NO_RES_RANGE  = 4194304
+	FILTER_FLAG_GLOBAL_RANGE  = 268435456
 
-	// URL flags
+	// URL flags (SCHEME_REQUIRED and HOST_REQUIRED are implied since PHP 8)
 	FILTER_FLAG_SCHEME_REQUIRED = 65536
 	FILTER_FLAG_HOST_REQUIRED   = 131072
 	FILTER_FLAG_PATH_REQUIRED   = 262144
 	FILTER_FLAG_QUERY_REQUIRED  = 524288
 
-	// Regex flags
+	// Domain and email flags
+	FILTER_FLAG_HOSTNAME      = 1048576
+	FILTER_FLAG_EMAIL_UNICODE = 1048576
+
+	// Scalar and array requirements
 	FILTER_REQUIRE_SCALAR = 33554432
 	FILTER_REQUIRE_ARRAY  = 16777216
+	FILTER_FORCE_ARRAY    = 67108864
 
 	// Other
 	FILTER_NULL_ON_FAILURE = 134217728
 )
 
-// ============================================================================
-// Filter Var
-// ============================================================================
+// Input sources of filter_input(), filter_input_array() and filter_has_var()
+const (
+	INPUT_POST   = 0
+	INPUT_GET    = 1
+	INPUT_COOKIE = 2
+	INPUT_ENV    = 4
+	INPUT_SERVER = 5
+)
 
-// FilterVar filters a variable with a specified filter
-// filter_var(mixed $value, int $filter = FILTER_DEFAULT, array|int $options = []): mixed
-func FilterVar(value *types.Value, args ...*types.Value) *types.Value {
-	filter := FILTER_UNSAFE_RAW
-	flags := 0
+// InputSuperglobals maps the input sources to the superglobals the SAPI
+// layer populates from the same data
+var InputSuperglobals = map[int64]string{
+	INPUT_POST:   "_POST",
+	INPUT_GET:    "_GET",
+	INPUT_COOKIE: "_COOKIE",
+	INPUT_ENV:    "_ENV",
+	INPUT_SERVER: "_SERVER",
+}
 
-	if len(args) > 0 && args[0] != nil {
-		filter = int(args[0].ToInt())
+// Constants returns the filter constants defined by PHP
+func Constants() map[string]*types.Value {
+	constants := make(map[string]*types.Value)
+	for name, value := range map[string]int64{
+		"INPUT_POST":   INPUT_POST,
+		"INPUT_GET":    INPUT_GET,
+		"INPUT_COOKIE": INPUT_COOKIE,
+		"INPUT_ENV":    INPUT_ENV,
+		"INPUT_SERVER": INPUT_SERVER,
+
+		"FILTER_FLAG_NONE":       FILTER_FLAG_NONE,
+		"FILTER_REQUIRE_SCALAR":  FILTER_REQUIRE_SCALAR,
+		"FILTER_REQUIRE_ARRAY":   FILTER_REQUIRE_ARRAY,
+		"FILTER_FORCE_ARRAY":     FILTER_FORCE_ARRAY,
+		"FILTER_NULL_ON_FAILURE": FILTER_NULL_ON_FAILURE,
+
+		"FILTER_VALIDATE_INT":     FILTER_VALIDATE_INT,
+		"FILTER_VALIDATE_BOOLEAN": FILTER_VALIDATE_BOOLEAN,
+		"FILTER_VALIDATE_BOOL":    FILTER_VALIDATE_BOOL,
+		"FILTER_VALIDATE_FLOAT":   FILTER_VALIDATE_FLOAT,
+		"FILTER_VALIDATE_REGEXP":  FILTER_VALIDATE_REGEXP,
+		"FILTER_VALIDATE_DOMAIN":  FILTER_VALIDATE_DOMAIN,
+		"FILTER_VALIDATE_URL":     FILTER_VALIDATE_URL,
+		"FILTER_VALIDATE_EMAIL":   FILTER_VALIDATE_EMAIL,
+		"FILTER_VALIDATE_IP":      FILTER_VALIDATE_IP,
+		"FILTER_VALIDATE_MAC":     FILTER_VALIDATE_MAC,
+
+		"FILTER_DEFAULT":                     FILTER_DEFAULT,
+		"FILTER_UNSAFE_RAW":                  FILTER_UNSAFE_RAW,
+		"FILTER_SANITIZE_STRING":             FILTER_SANITIZE_STRING,
+		"FILTER_SANITIZE_STRIPPED":           FILTER_SANITIZE_STRIPPED,
+		"FILTER_SANITIZE_ENCODED":            FILTER_SANITIZE_ENCODED,
+		"FILTER_SANITIZE_SPECIAL_CHARS":      FILTER_SANITIZE_SPECIAL_CHARS,
+		"FILTER_SANITIZE_FULL_SPECIAL_CHARS": FILTER_SANITIZE_FULL_SPECIAL_CHARS,
+		"FILTER_SANITIZE_EMAIL":              FILTER_SANITIZE_EMAIL,
+		"FILTER_SANITIZE_URL":                FILTER_SANITIZE_URL,
+		"FILTER_SANITIZE_NUMBER_INT":         FILTER_SANITIZE_NUMBER_INT,
+		"FILTER_SANITIZE_NUMBER_FLOAT":       FILTER_SANITIZE_NUMBER_FLOAT,
+		"FILTER_SANITIZE_ADD_SLASHES":        FILTER_SANITIZE_ADD_SLASHES,
+		"FILTER_CALLBACK":                    FILTER_CALLBACK,
+
+		"FILTER_FLAG_ALLOW_OCTAL":       FILTER_FLAG_ALLOW_OCTAL,
+		"FILTER_FLAG_ALLOW_HEX":         FILTER_FLAG_ALLOW_HEX,
+		"FILTER_FLAG_STRIP_LOW":         FILTER_FLAG_STRIP_LOW,
+		"FILTER_FLAG_STRIP_HIGH":        FILTER_FLAG_STRIP_HIGH,
+		"FILTER_FLAG_STRIP_BACKTICK":    FILTER_FLAG_STRIP_BACKTICK,
+		"FILTER_FLAG_ENCODE_LOW":        FILTER_FLAG_ENCODE_LOW,
+		"FILTER_FLAG_ENCODE_HIGH":       FILTER_FLAG_ENCODE_HIGH,
+		"FILTER_FLAG_ENCODE_AMP":        FILTER_FLAG_ENCODE_AMP,
+		"FILTER_FLAG_NO_ENCODE_QUOTES":  FILTER_FLAG_NO_ENCODE_QUOTES,
+		"FILTER_FLAG_EMPTY_STRING_NULL": FILTER_FLAG_EMPTY_STRING_NULL,
+		"FILTER_FLAG_ALLOW_FRACTION":    FILTER_FLAG_ALLOW_FRACTION,
+		"FILTER_FLAG_ALLOW_THOUSAND":    FILTER_FLAG_ALLOW_THOUSAND,
+		"FILTER_FLAG_ALLOW_SCIENTIFIC":  FILTER_FLAG_ALLOW_SCIENTIFIC,
+		"FILTER_FLAG_PATH_REQUIRED":     FILTER_FLAG_PATH_REQUIRED,
+		"FILTER_FLAG_QUERY_REQUIRED":    FILTER_FLAG_QUERY_REQUIRED,
+		"FILTER_FLAG_IPV4":              FILTER_FLAG_IPV4,
+		"FILTER_FLAG_IPV6":              FILTER_FLAG_IPV6,
+		"FILTER_FLAG_NO_RES_RANGE":      FILTER_FLAG_NO_RES_RANGE,
+		"FILTER_FLAG_NO_PRIV_RANGE":     FILTER_FLAG_NO_PRIV_RANGE,
+		"FILTER_FLAG_GLOBAL_RANGE":      FILTER_FLAG_GLOBAL_RANGE,
+		"FILTER_FLAG_HOSTNAME":          FILTER_FLAG_HOSTNAME,
+		"FILTER_FLAG_EMAIL_UNICODE":     FILTER_FLAG_EMAIL_UNICODE,
+	} {
+		constants[name] = types.NewInt(value)
+	}
+	return constants
+}
+
+// filterNames lists the filters by name, in the order of filter_list()
+var filterNames = []struct {
+	name string
+	id   int64
+}{
+	{"int", FILTER_VALIDATE_INT},
+	{"boolean", FILTER_VALIDATE_BOOLEAN},
+	{"bool", FILTER_VALIDATE_BOOL},
+	{"float", FILTER_VALIDATE_FLOAT},
+	{"validate_regexp", FILTER_VALIDATE_REGEXP},
+	{"validate_domain", FILTER_VALIDATE_DOMAIN},
+	{"validate_url", FILTER_VALIDATE_URL},
+	{"validate_email", FILTER_VALIDATE_EMAIL},
+	{"validate_ip", FILTER_VALIDATE_IP},
+	{"validate_mac", FILTER_VALIDATE_MAC},
+	{"string", FILTER_SANITIZE_STRING},
+	{"stripped", FILTER_SANITIZE_STRIPPED},
+	{"encoded", FILTER_SANITIZE_ENCODED},
+	{"special_chars", FILTER_SANITIZE_SPECIAL_CHARS},
+	{"full_special_chars", FILTER_SANITIZE_FULL_SPECIAL_CHARS},
+	{"unsafe_raw", FILTER_UNSAFE_RAW},
+	{"email", FILTER_SANITIZE_EMAIL},
+	{"url", FILTER_SANITIZE_URL},
+	{"number_int", FILTER_SANITIZE_NUMBER_INT},
+	{"number_float", FILTER_SANITIZE_NUMBER_FLOAT},
+	{"add_slashes", FILTER_SANITIZE_ADD_SLASHES},
+	{"callback", FILTER_CALLBACK},
+}
+
+// FilterList returns the names of the supported filters
+// filter_list(): array
+func FilterList() *types.Value {
+	names := types.NewEmptyArray()
+	for _, filter := range filterNames {
+		names.Append(types.NewString(filter.name))
 	}
+	return types.NewArray(names)
+}
 
-	if len(args) > 1 && args[1] != nil {
-		// Can be int (flags) or array (options)
-		if args[1].Type() == types.TypeInt {
-			flags = int(args[1].ToInt())
+// FilterID returns the ID of a filter by name; false if there is none
+// filter_id(string $name): int|false
+func FilterID(name *types.Value) *types.Value {
+	for _, filter := range filterNames {
+		if filter.name == name.ToString() {
+			return types.NewInt(filter.id)
 		}
-		// TODO: Handle array options
 	}
-
-	return applyFilter(value, filter, flags)
+	return types.NewBool(false)
 }
 
-// applyFilter applies the specified filter to a value
-func applyFilter(value *types.Value, filter int, flags int) *types.Value {
-	str := value.ToString()
-
-	switch filter {
-	// Validation filters
-	case FILTER_VALIDATE_BOOLEAN:
-		return validateBoolean(str, flags)
-	case FILTER_VALIDATE_EMAIL:
-		return validateEmail(str)
-	case FILTER_VALIDATE_FLOAT:
-		return validateFloat(str, flags)
-	case FILTER_VALIDATE_INT:
-		return validateInt(str, flags)
-	case FILTER_VALIDATE_IP:
-		return validateIP(str, flags)
-	case FILTER_VALIDATE_MAC:
-		return validateMAC(str)
-	case FILTER_VALIDATE_URL:
-		return validateURL(str, flags)
-	case FILTER_VALIDATE_DOMAIN:
-		return validateDomain(str)
+// Error is an error thrown by a filter function, such as the ValueError
+// of a "decimal" option longer than one character
+type Error struct {
+	Class   string
+	Message string
+}
 
-	// Sanitize filters
-	case FILTER_SANITIZE_EMAIL:
-		return sanitizeEmail(str)
-	case FILTER_SANITIZE_NUMBER_FLOAT:
-		return sanitizeNumberFloat(str, flags)
-	case FILTER_SANITIZE_NUMBER_INT:
-		return sanitizeNumberInt(str)
-	case FILTER_SANITIZE_STRING:
-		return sanitizeString(str, flags)
-	case FILTER_SANITIZE_URL:
-		return sanitizeURL(str)
-
-	// Raw/default
-	case FILTER_UNSAFE_RAW:
-		return value
-
-	default:
-		return types.NewBool(false)
-	}
+func (e *Error) Error() string {
+	return e.Message
 }
 
 // ============================================================================
-// Validation Functions
+// Filter
 // ============================================================================
 
-func validateBoolean(str string, flags int) *types.Value {
-	str = strings.TrimSpace(strings.ToLower(str))
-
-	truthy := []string{"1", "true", "on", "yes"}
-	falsy := []string{"0", "false", "off", "no", ""}
-
-	for _, t := range truthy {
-		if str == t {
-			return types.NewBool(true)
-		}
-	}
-
-	for _, f := range falsy {
-		if str == f {
-			return types.NewBool(true)
-		}
-	}
-
-	return types.NewBool(false)
+// Filter runs the filter functions with the hooks they need from the
+// engine. The zero value filters plain values: objects are not
+// Stringable, callbacks cannot be called and no input is available.
+type Filter struct {
+	Caller   stdlib.Caller                                   // Calls FILTER_CALLBACK callbacks
+	Warning  func(message string)                            // Receives warnings (nil discards them)
+	Stringer func(object *types.Value) (string, bool, error) // Converts Stringable objects; false if not Stringable
+	Source   func(source int64) *types.Array                 // Returns the request data of an INPUT_* source; nil if none
 }
 
-func validateEmail(str string) *types.Value {
-	// Basic email validation
-	_, err := mail.ParseAddress(str)
+// FilterVar filters a variable with a specified filter
+// filter_var(mixed $value, int $filter = FILTER_DEFAULT, array|int $options = 0): mixed
+func FilterVar(value *types.Value, args ...*types.Value) *types.Value {
+	result, err := (&Filter{}).Var(value, args...)
 	if err != nil {
 		return types.NewBool(false)
 	}
-	return types.NewString(str)
+	return result
 }
 
-func validateFloat(str string, flags int) *types.Value {
-	str = strings.TrimSpace(str)
-
-	// Handle thousand separator if allowed
-	if flags&FILTER_FLAG_ALLOW_THOUSAND != 0 {
-		str = strings.ReplaceAll(str, ",", "")
-	}
-
-	// Try to parse as float
-	_, err := strconv.ParseFloat(str, 64)
+// FilterVarArray filters multiple variables
+// filter_var_array(array $array, array|int $options = FILTER_DEFAULT, bool $add_empty = true): array|false|null
+func FilterVarArray(arr *types.Value, args ...*types.Value) *types.Value {
+	result, err := (&Filter{}).VarArray(arr, args...)
 	if err != nil {
 		return types.NewBool(false)
 	}
-
-	return types.NewString(str)
+	return result
 }
 
-func validateInt(str string, flags int) *types.Value {
-	str = strings.TrimSpace(str)
-
-	// Check for different bases
-	base := 10
-
-	if flags&FILTER_FLAG_ALLOW_HEX != 0 {
-		if strings.HasPrefix(str, "0x") || strings.HasPrefix(str, "0X") {
-			base = 16
-			str = str[2:]
-		}
+// Var filters a value. The options are the flags, or an array with the
+// "flags" and the "options" of the filter (the callback of
+// FILTER_CALLBACK). Validation failures give false, or null with
+// FILTER_NULL_ON_FAILURE, unless a "default" option replaces them.
+// filter_var(mixed $value, int $filter = FILTER_DEFAULT, array|int $options = 0): mixed
+func (f *Filter) Var(value *types.Value, args ...*types.Value) (*types.Value, error) {
+	filter := int64(FILTER_DEFAULT)
+	if len(args) > 0 && args[0] != nil {
+		filter = args[0].ToInt()
 	}
-
-	if flags&FILTER_FLAG_ALLOW_OCTAL != 0 {
-		if strings.HasPrefix(str, "0") && len(str) > 1 {
-			base = 8
-			str = str[1:]
-		}
+	if !knownFilter(filter) {
+		f.warning("filter_var(): Unknown filter with ID %d", filter)
+		return types.NewBool(false), nil
 	}
+	return f.run("filter_var").call(value.Deref().DeepCopy(), filter, optionalArg(args, 1), FILTER_REQUIRE_SCALAR)
+}
 
-	// Try to parse as int
-	_, err := strconv.ParseInt(str, base, 64)
-	if err != nil {
-		return types.NewBool(false)
-	}
+// VarArray filters the entries of an array: with a filter ID all of
+// them, recursively; with a definition array the entries it names, each
+// with its filter ID or options array. Entries the definition names that
+// are missing are null if addEmpty is set (the default).
+// filter_var_array(array $array, array|int $options = FILTER_DEFAULT, bool $add_empty = true): array|false|null
+func (f *Filter) VarArray(arr *types.Value, args ...*types.Value) (*types.Value, error) {
+	arr = arr.Deref()
+	if !arr.IsArray() {
+		return types.NewBool(false), nil
+	}
+	definition := optionalArg(args, 0)
+	if definition == nil {
+		definition = types.NewInt(FILTER_DEFAULT)
+	}
+	if !definition.IsArray() && !knownFilter(definition.ToInt()) {
+		f.warning("filter_var_array(): Unknown filter with ID %d", definition.ToInt())
+		return types.NewBool(false), nil
+	}
+	return f.run("filter_var_array").array(arr.ToArray(), definition, addEmpty(args, 1))
+}
+
+// ============================================================================
+// Filtering
+// ============================================================================
 
-	return types.NewString(str)
+// run is a filter function being run, whose name its errors carry
+type run struct {
+	*Filter
+	function string
 }
 
-func validateIP(str string, flags int) *types.Value {
-	ip := net.ParseIP(str)
-	if ip == nil {
-		return types.NewBool(false)
-	}
+func (f *Filter) run(function string) run {
+	return run{Filter: f, function: function}
+}
 
-	// Check IPv4/IPv6 flags
-	if flags&FILTER_FLAG_IPV4 != 0 {
-		if ip.To4() == nil {
-			return types.NewBool(false)
-		}
+func (f *Filter) warning(format string, args ...any) {
+	if f.Warning != nil {
+		f.Warning(fmt.Sprintf(format, args...))
 	}
+}
 
-	if flags&FILTER_FLAG_IPV6 != 0 {
-		if ip.To4() != nil {
-			return types.NewBool(false)
+// call filters a value as its definition says: nil or the flags as an
+// int, or an options array. A filter of -1 is taken from the definition
+// (an int is then the filter, not the flags).
+func (r run) call(value *types.Value, filter int64, definition *types.Value, flags int64) (*types.Value, error) {
+	var options *types.Value
+	if definition != nil && definition.IsArray() {
+		def := definition.ToArray()
+		if option, ok := def.Get(types.NewString("filter")); ok {
+			filter = option.Deref().ToInt()
 		}
-	}
-
-	// Check private range
-	if flags&FILTER_FLAG_NO_PRIV_RANGE != 0 {
-		if ip.IsPrivate() {
-			return types.NewBool(false)
+		if option, ok := def.Get(types.NewString("flags")); ok {
+			flags = requireScalar(option.Deref().ToInt())
+		}
+		if option, ok := def.Get(types.NewString("options")); ok {
+			option = option.Deref()
+			if filter == FILTER_CALLBACK {
+				options, flags = option, 0
+			} else if option.IsArray() {
+				options = option
+			}
+		}
+	} else {
+		long := int64(0)
+		if definition != nil {
+			long = definition.ToInt()
+		}
+		if filter != -1 {
+			flags = requireScalar(long)
+		} else {
+			filter = long
 		}
 	}
 
-	// Check reserved range
-	if flags&FILTER_FLAG_NO_RES_RANGE != 0 {
-		if ip.IsLoopback() || ip.IsMulticast() {
-			return types.NewBool(false)
+	if value.IsArray() {
+		if flags&FILTER_REQUIRE_SCALAR != 0 {
+			return failure(flags), nil
 		}
+		return r.recursive(value.ToArray(), filter, flags, options)
 	}
-
-	return types.NewString(str)
-}
-
-func validateMAC(str string) *types.Value {
-	// MAC address validation
-	_, err := net.ParseMAC(str)
-	if err != nil {
-		return types.NewBool(false)
+	if flags&FILTER_REQUIRE_ARRAY != 0 {
+		return failure(flags), nil
 	}
-	return types.NewString(str)
-}
-
-func validateURL(str string, flags int) *types.Value {
-	u, err := url.Parse(str)
+	result, err := r.filter(value, filter, flags, options)
 	if err != nil {
-		return types.NewBool(false)
+		return nil, err
 	}
-
-	// URL must have a scheme and host by default
-	if u.Scheme == "" || u.Host == "" {
-		return types.NewBool(false)
-	}
-
-	// Check required components
-	if flags&FILTER_FLAG_SCHEME_REQUIRED != 0 && u.Scheme == "" {
-		return types.NewBool(false)
-	}
-
-	if flags&FILTER_FLAG_HOST_REQUIRED != 0 && u.Host == "" {
-		return types.NewBool(false)
+	if flags&FILTER_FORCE_ARRAY != 0 {
+		wrapped := types.NewEmptyArray()
+		wrapped.Append(result)
+		result = types.NewArray(wrapped)
 	}
+	return result, nil
+}
 
-	if flags&FILTER_FLAG_PATH_REQUIRED != 0 && u.Path == "" {
-		return types.NewBool(false)
+// requireScalar adds FILTER_REQUIRE_SCALAR to flags that do not ask for
+// an array
+func requireScalar(flags int64) int64 {
+	if flags&(FILTER_REQUIRE_ARRAY|FILTER_FORCE_ARRAY) == 0 {
+		flags |= FILTER_REQUIRE_SCALAR
 	}
-
-	if flags&FILTER_FLAG_QUERY_REQUIRED != 0 && u.RawQuery == "" {
-		return types.NewBool(false)
-	}
-
-	return types.NewString(str)
+	return flags
 }
 
-func validateDomain(str string) *types.Value {
-	// Basic domain validation
-	if str == "" {
-		return types.NewBool(false)
+// failure returns the result of a failed validation
+func failure(flags int64) *types.Value {
+	if flags&FILTER_NULL_ON_FAILURE != 0 {
+		return types.NewNull()
 	}
+	return types.NewBool(false)
+}
 
-	// Simple regex for domain validation
-	domainRegex := regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]{2,}$`)
-	if !domainRegex.MatchString(str) {
-		return types.NewBool(false)
+// recursive filters the scalars of an array, keeping its structure
+func (r run) recursive(arr *types.Array, filter, flags int64, options *types.Value) (*types.Value, error) {
+	result := types.NewEmptyArray()
+	var err error
+	arr.Each(func(key, value *types.Value) bool {
+		value = value.Deref()
+		var filtered *types.Value
+		if value.IsArray() {
+			filtered, err = r.recursive(value.ToArray(), filter, flags, options)
+		} else {
+			filtered, err = r.filter(value, filter, flags, options)
+		}
+		result.Set(key, filtered)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	return types.NewString(str)
+	return types.NewArray(result), nil
 }
 
-// ============================================================================
-// Sanitization Functions
-// ============================================================================
-
-func sanitizeEmail(str string) *types.Value {
-	// Remove all characters except letters, digits and !#$%&'*+-=?^_`{|}~@.[]
-	// Note: '<' and '>' are specifically NOT allowed in email addresses
-	var result strings.Builder
-	for _, r := range str {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') ||
-			r == '!' || r == '#' || r == '$' || r == '%' || r == '&' || r == '\'' ||
-			r == '*' || r == '+' || r == '-' || r == '=' || r == '?' || r == '^' ||
-			r == '_' || r == '`' || r == '{' || r == '|' || r == '}' || r == '~' ||
-			r == '@' || r == '.' {
-			result.WriteRune(r)
+// filter applies a filter to a scalar or object, converted to a string
+// first; an unknown filter is FILTER_DEFAULT. A failure is replaced by
+// the "default" option if there is one.
+func (r run) filter(value *types.Value, filter, flags int64, options *types.Value) (*types.Value, error) {
+	apply, ok := filters[filter]
+	if !ok {
+		apply = filters[FILTER_DEFAULT]
+	}
+
+	str, convertible := value.ToString(), true
+	if value.IsObject() {
+		convertible = false
+		if r.Stringer != nil {
+			var err error
+			if str, convertible, err = r.Stringer(value); err != nil {
+				return nil, err
+			}
 		}
-		// Note: '<', '>', '[', ']', and other special chars are filtered out
 	}
-	return types.NewString(result.String())
-}
 
-func sanitizeNumberFloat(str string, flags int) *types.Value {
-	// Keep only digits, +, -, ., and optionally , and e/E
-	var result strings.Builder
-	for _, r := range str {
-		if r >= '0' && r <= '9' {
-			result.WriteRune(r)
-		} else if r == '+' || r == '-' || r == '.' {
-			result.WriteRune(r)
-		} else if flags&FILTER_FLAG_ALLOW_FRACTION != 0 && r == ',' {
-			result.WriteRune(r)
-		} else if flags&FILTER_FLAG_ALLOW_SCIENTIFIC != 0 && (r == 'e' || r == 'E') {
-			result.WriteRune(r)
+	var result *types.Value
+	if convertible {
+		var err error
+		if result, err = apply(r, str, flags, options); err != nil {
+			return nil, err
 		}
 	}
-	return types.NewString(result.String())
-}
+	if result == nil {
+		result = failure(flags)
+	}
 
-func sanitizeNumberInt(str string) *types.Value {
-	// Keep only digits, + and -
-	var result strings.Builder
-	for _, r := range str {
-		if (r >= '0' && r <= '9') || r == '+' || r == '-' {
-			result.WriteRune(r)
+	failed := result.IsNull() && flags&FILTER_NULL_ON_FAILURE != 0 ||
+		result.IsBool() && !result.ToBool() && flags&FILTER_NULL_ON_FAILURE == 0
+	if failed && options != nil && options.IsArray() {
+		if def, ok := options.ToArray().Get(types.NewString("default")); ok {
+			result = def.Deref()
 		}
 	}
-	return types.NewString(result.String())
+	return result, nil
 }
 
-func sanitizeString(str string, flags int) *types.Value {
-	// Strip tags and encode special characters
-	// For now, a simplified version
-	var result strings.Builder
-	inTag := false
+// array filters the entries of an array with a filter_var_array()
+// definition
+func (r run) array(arr *types.Array, definition *types.Value, addEmpty bool) (*types.Value, error) {
+	if !definition.IsArray() {
+		return r.call(types.NewArray(arr).DeepCopy(), -1, definition, FILTER_REQUIRE_ARRAY)
+	}
 
-	for _, r := range str {
-		if r == '<' {
-			inTag = true
-			continue
+	result := types.NewEmptyArray()
+	var err error
+	definition.ToArray().Each(func(key, def *types.Value) bool {
+		if !key.IsString() {
+			err = &Error{Class: "TypeError", Message: r.function + "(): Argument #2 ($options) must contain only string keys"}
+			return false
 		}
-		if r == '>' {
-			inTag = false
-			continue
+		if key.ToString() == "" {
+			err = &Error{Class: "ValueError", Message: r.function + "(): Argument #2 ($options) cannot contain empty keys"}
+			return false
 		}
-		if !inTag {
-			// Strip low ASCII if flag set
-			if flags&FILTER_FLAG_STRIP_LOW != 0 && r < 32 {
-				continue
-			}
-			// Strip high ASCII if flag set
-			if flags&FILTER_FLAG_STRIP_HIGH != 0 && r >= 128 {
-				continue
+		value, ok := arr.Get(key)
+		if !ok {
+			if addEmpty {
+				result.Set(key, types.NewNull())
 			}
-			result.WriteRune(r)
+			return true
 		}
+		var filtered *types.Value
+		filtered, err = r.call(value.Deref().DeepCopy(), -1, def.Deref(), FILTER_REQUIRE_SCALAR)
+		result.Set(key, filtered)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	return types.NewString(result.String())
+	return types.NewArray(result), nil
 }
 
-func sanitizeURL(str string) *types.Value {
-	// Remove all characters except letters, digits and $-_.+!*'(),{}|\\^~[]`<>#%";/?:@&=
-	var result strings.Builder
-	for _, r := range str {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') ||
-			r == '$' || r == '-' || r == '_' || r == '.' || r == '+' ||
-			r == '!' || r == '*' || r == '\'' || r == '(' || r == ')' ||
-			r == ',' || r == '{' || r == '}' || r == '|' || r == '\\' ||
-			r == '^' || r == '~' || r == '[' || r == ']' || r == '`' ||
-			r == '<' || r == '>' || r == '#' || r == '%' || r == '"' ||
-			r == ';' || r == '/' || r == '?' || r == ':' || r == '@' ||
-			r == '&' || r == '=' {
-			result.WriteRune(r)
-		}
-	}
-	return types.NewString(result.String())
+// knownFilter reports whether a filter ID is supported
+func knownFilter(id int64) bool {
+	_, ok := filters[id]
+	return ok
 }
 
-// ============================================================================
-// Filter Var Array
-// ============================================================================
-
-// FilterVarArray filters multiple variables
-// filter_var_array(array $array, array|int $definition = FILTER_DEFAULT): array|false
-func FilterVarArray(arr *types.Value, args ...*types.Value) *types.Value {
-	if arr.Type() != types.TypeArray {
-		return types.NewBool(false)
+// optionalArg returns the dereferenced argument at index i; nil if it
+// was not given
+func optionalArg(args []*types.Value, i int) *types.Value {
+	if i < len(args) && args[i] != nil {
+		return args[i].Deref()
 	}
+	return nil
+}
 
-	filter := FILTER_UNSAFE_RAW
-	if len(args) > 0 && args[0] != nil {
-		filter = int(args[0].ToInt())
+// addEmpty returns the add_empty argument at index i, true by default
+func addEmpty(args []*types.Value, i int) bool {
+	if arg := optionalArg(args, i); arg != nil {
+		return arg.ToBool()
 	}
+	return true
+}
 
-	inputArr := arr.ToArray()
-	result := types.NewEmptyArray()
+// filterFunc applies a filter to a string with its flags and options; a
+// nil result is a validation failure
+type filterFunc func(r run, value string, flags int64, options *types.Value) (*types.Value, error)
+
+// filters are the filters by ID
+var filters = map[int64]filterFunc{
+	FILTER_VALIDATE_INT:     validateInt,
+	FILTER_VALIDATE_BOOLEAN: validateBoolean,
+	FILTER_VALIDATE_FLOAT:   validateFloat,
+	FILTER_VALIDATE_REGEXP:  validateRegexp,
+	FILTER_VALIDATE_DOMAIN:  validateDomain,
+	FILTER_VALIDATE_URL:     validateURL,
+	FILTER_VALIDATE_EMAIL:   validateEmail,
+	FILTER_VALIDATE_IP:      validateIP,
+	FILTER_VALIDATE_MAC:     validateMAC,
+
+	FILTER_SANITIZE_STRING:             sanitizeString,
+	FILTER_SANITIZE_ENCODED:            sanitizer(sanitizeEncoded),
+	FILTER_SANITIZE_SPECIAL_CHARS:      sanitizer(sanitizeSpecialChars),
+	FILTER_SANITIZE_FULL_SPECIAL_CHARS: sanitizer(sanitizeFullSpecialChars),
+	FILTER_UNSAFE_RAW:                  unsafeRaw,
+	FILTER_SANITIZE_EMAIL:              sanitizer(sanitizeEmail),
+	FILTER_SANITIZE_URL:                sanitizer(sanitizeURL),
+	FILTER_SANITIZE_NUMBER_INT:         sanitizer(sanitizeNumberInt),
+	FILTER_SANITIZE_NUMBER_FLOAT:       sanitizer(sanitizeNumberFloat),
+	FILTER_SANITIZE_ADD_SLASHES:        sanitizer(sanitizeAddSlashes),
+	FILTER_CALLBACK:                    callback,
+}
 
-	inputArr.Each(func(key, value *types.Value) bool {
-		filtered := applyFilter(value, filter, 0)
-		result.Set(key, filtered)
-		return true
-	})
+// option returns an entry of an options array; false if it is missing
+func option(options *types.Value, name string) (*types.Value, bool) {
+	if options == nil || !options.IsArray() {
+		return nil, false
+	}
+	value, ok := options.ToArray().Get(types.NewString(name))
+	if !ok {
+		return nil, false
+	}
+	return value.Deref(), true
+}
 
-	return types.NewArray(result)
+// callback calls the callable of the options with the value and returns
+// its result
+func callback(r run, value string, flags int64, options *types.Value) (*types.Value, error) {
+	if options == nil || r.Caller == nil || !(options.IsString() || options.IsArray() || options.IsObject()) {
+		return nil, &Error{Class: "TypeError", Message: r.function + "(): Option must be a valid callback"}
+	}
+	result, err := r.Caller.CallCallable(options, []*types.Value{types.NewString(value)})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return types.NewNull(), nil
+	}
+	return result, nil
 }
@@ -19,11 +19,11 @@ func TestFilterVarValidateBoolean(t *testing.T) {
 		{"true", true},
 		{"on", true},
 		{"yes", true},
-		{"0", true},
-		{"false", true},
-		{"off", true},
-		{"no", true},
-		{"", true},
+		{"0", false},
+		{"false", false},
+		{"off", false},
+		{"no", false},
+		{"", false},
 		{"invalid", false},
 		{"2", false},
 	}
@@ -87,8 +87,8 @@ func TestFilterVarValidateFloat(t *testing.T) {
 	for _, tt := range tests {
 		result := FilterVar(types.NewString(tt.input), types.NewInt(FILTER_VALIDATE_FLOAT))
 		if tt.valid {
-			if result.Type() != types.TypeString {
-				t.Errorf("FilterVar(%q, FILTER_VALIDATE_FLOAT) should return string, got %v", tt.input, result.Type())
+			if result.Type() != types.TypeFloat {
+				t.Errorf("FilterVar(%q, FILTER_VALIDATE_FLOAT) should return float, got %v", tt.input, result.Type())
 			}
 		} else {
 			if result.Type() != types.TypeBool || result.ToBool() != false {
@@ -118,8 +118,8 @@ func TestFilterVarValidateInt(t *testing.T) {
 	for _, tt := range tests {
 		result := FilterVar(types.NewString(tt.input), types.NewInt(FILTER_VALIDATE_INT))
 		if tt.valid {
-			if result.Type() != types.TypeString {
-				t.Errorf("FilterVar(%q, FILTER_VALIDATE_INT) should return string, got %v", tt.input, result.Type())
+			if result.Type() != types.TypeInt {
+				t.Errorf("FilterVar(%q, FILTER_VALIDATE_INT) should return int, got %v", tt.input, result.Type())
 			}
 		} else {
 			if result.Type() != types.TypeBool || result.ToBool() != false {
@@ -136,8 +136,8 @@ func TestFilterVarValidateIntHex(t *testing.T) {
 		types.NewInt(FILTER_FLAG_ALLOW_HEX),
 	)
 
-	if result.Type() != types.TypeString {
-		t.Errorf("FilterVar('0xFF', FILTER_VALIDATE_INT, FILTER_FLAG_ALLOW_HEX) should return string")
+	if result.Type() != types.TypeInt || result.ToInt() != 255 {
+		t.Errorf("FilterVar('0xFF', FILTER_VALIDATE_INT, FILTER_FLAG_ALLOW_HEX) should return 255, got %v", result)
 	}
 }
 
@@ -265,7 +265,8 @@ func TestFilterVarValidateDomain(t *testing.T) {
 		{"example.com", true},
 		{"sub.example.com", true},
 		{"example.co.uk", true},
-		{"invalid", false},
+		{"localhost", true},
+		{"a..b", false},
 		{"", false},
 		{".com", false},
 	}
@@ -316,11 +317,11 @@ func TestFilterVarSanitizeNumberFloat(t *testing.T) {
 		input    string
 		expected string
 	}{
-		{"3.14", "3.14"},
-		{"12.34abc", "12.34"},
-		{"+3.14", "+3.14"},
-		{"-2.5", "-2.5"},
-		{"1,234.56", "1234.56"},
+		{"3.14", "314"},
+		{"12.34abc", "1234"},
+		{"+3.14", "+314"},
+		{"-2.5", "-25"},
+		{"1,234.56", "123456"},
 	}
 
 	for _, tt := range tests {
@@ -365,7 +366,7 @@ func TestFilterVarSanitizeString(t *testing.T) {
 		expected string
 	}{
 		{"hello", "hello"},
-		{"hello<script>alert('xss')</script>", "helloalert('xss')"},
+		{"hello<script>alert('xss')</script>", "helloalert(&#39;xss&#39;)"},
 		{"<b>bold</b>", "bold"},
 	}
 
@@ -0,0 +1,124 @@
+package filter
+
+import (
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Request Input
+// ============================================================================
+
+// The input functions read the request data the SAPI layer received, not
+// the superglobals: a script changing $_GET does not change what
+// filter_input(INPUT_GET, ...) sees.
+
+// source returns the request data of an input source; nil if the source
+// has none. An unknown source is a ValueError naming the argument.
+func (r run) source(source *types.Value, argument string) (*types.Array, error) {
+	kind := source.Deref().ToInt()
+	if _, ok := InputSuperglobals[kind]; !ok {
+		return nil, &Error{Class: "ValueError", Message: r.function + "(): Argument #1 (" + argument + ") must be an INPUT_* constant"}
+	}
+	if r.Source == nil {
+		return nil, nil
+	}
+	return r.Source(kind), nil
+}
+
+// HasVar reports whether an input source has a variable
+// filter_has_var(int $input_type, string $var_name): bool
+func (f *Filter) HasVar(source, name *types.Value) (*types.Value, error) {
+	input, err := f.run("filter_has_var").source(source, "$input_type")
+	if err != nil {
+		return nil, err
+	}
+	if input == nil {
+		return types.NewBool(false), nil
+	}
+	_, ok := input.Get(types.NewString(name.Deref().ToString()))
+	return types.NewBool(ok), nil
+}
+
+// Input filters a variable of an input source like Var does. A missing
+// variable gives the "default" option if there is one, else null, or
+// false with FILTER_NULL_ON_FAILURE (which inverts the usual results).
+// filter_input(int $type, string $var_name, int $filter = FILTER_DEFAULT, array|int $options = 0): mixed
+func (f *Filter) Input(source, name *types.Value, args ...*types.Value) (*types.Value, error) {
+	r := f.run("filter_input")
+	filter := int64(FILTER_DEFAULT)
+	if arg := optionalArg(args, 0); arg != nil {
+		filter = arg.ToInt()
+	}
+	if !knownFilter(filter) {
+		f.warning("filter_input(): Unknown filter with ID %d", filter)
+		return types.NewBool(false), nil
+	}
+	input, err := r.source(source, "$type")
+	if err != nil {
+		return nil, err
+	}
+
+	definition := optionalArg(args, 1)
+	var value *types.Value
+	found := false
+	if input != nil {
+		value, found = input.Get(types.NewString(name.Deref().ToString()))
+	}
+	if !found {
+		flags := int64(0)
+		if definition != nil && definition.IsArray() {
+			def := definition.ToArray()
+			if option, ok := def.Get(types.NewString("flags")); ok {
+				flags = option.Deref().ToInt()
+			}
+			if options, ok := def.Get(types.NewString("options")); ok && options.Deref().IsArray() {
+				if value, ok := options.Deref().ToArray().Get(types.NewString("default")); ok {
+					return value.Deref(), nil
+				}
+			}
+		} else if definition != nil {
+			flags = definition.ToInt()
+		}
+		if flags&FILTER_NULL_ON_FAILURE != 0 {
+			return types.NewBool(false), nil
+		}
+		return types.NewNull(), nil
+	}
+	return r.call(value.Deref().DeepCopy(), filter, definition, FILTER_REQUIRE_SCALAR)
+}
+
+// InputArray filters the variables of an input source like VarArray
+// does. A source without data gives null, or false with
+// FILTER_NULL_ON_FAILURE.
+// filter_input_array(int $type, array|int $options = FILTER_DEFAULT, bool $add_empty = true): array|false|null
+func (f *Filter) InputArray(source *types.Value, args ...*types.Value) (*types.Value, error) {
+	r := f.run("filter_input_array")
+	definition := optionalArg(args, 0)
+	if definition == nil {
+		definition = types.NewInt(FILTER_DEFAULT)
+	}
+	if !definition.IsArray() && !knownFilter(definition.ToInt()) {
+		f.warning("filter_input_array(): Unknown filter with ID %d", definition.ToInt())
+		return types.NewBool(false), nil
+	}
+	input, err := r.source(source, "$type")
+	if err != nil {
+		return nil, err
+	}
+
+	if input == nil {
+		flags := int64(0)
+		if definition.IsArray() {
+			if option, ok := definition.ToArray().Get(types.NewString("flags")); ok {
+				flags = option.Deref().ToInt()
+			}
+		} else {
+			flags = definition.ToInt()
+		}
+		if flags&FILTER_NULL_ON_FAILURE != 0 {
+			return types.NewBool(false), nil
+		}
+		return types.NewNull(), nil
+	}
+	return r.array(input, definition, addEmpty(args, 1))
+}
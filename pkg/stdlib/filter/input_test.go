@@ -0,0 +1,179 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/stdlib"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// assoc builds an array of string keys and values
+func assoc(pairs ...any) *types.Value {
+	arr := types.NewEmptyArray()
+	for i := 0; i < len(pairs); i += 2 {
+		var value *types.Value
+		switch v := pairs[i+1].(type) {
+		case string:
+			value = types.NewString(v)
+		case int:
+			value = types.NewInt(int64(v))
+		case *types.Value:
+			value = v
+		}
+		arr.Set(types.NewString(pairs[i].(string)), value)
+	}
+	return types.NewArray(arr)
+}
+
+func get(arr *types.Value, key string) *types.Value {
+	value, _ := arr.ToArray().Get(types.NewString(key))
+	return value
+}
+
+func TestFilterVarArraysAndScalars(t *testing.T) {
+	list := assoc("a", "1", "b", "x")
+
+	if result := FilterVar(list, types.NewInt(FILTER_VALIDATE_INT)); !result.IsBool() || result.ToBool() {
+		t.Errorf("arrays require FILTER_REQUIRE_ARRAY, got %v", result)
+	}
+	result := FilterVar(list, types.NewInt(FILTER_VALIDATE_INT), types.NewInt(FILTER_REQUIRE_ARRAY))
+	if get(result, "a").ToInt() != 1 || !get(result, "b").IsBool() {
+		t.Errorf("FILTER_REQUIRE_ARRAY: got %v", result)
+	}
+	if result := FilterVar(types.NewString("1"), types.NewInt(FILTER_VALIDATE_INT), types.NewInt(FILTER_REQUIRE_ARRAY)); !result.IsBool() {
+		t.Errorf("scalars fail FILTER_REQUIRE_ARRAY, got %v", result)
+	}
+	result = FilterVar(types.NewString("7"), types.NewInt(FILTER_VALIDATE_INT), types.NewInt(FILTER_FORCE_ARRAY))
+	if !result.IsArray() || result.ToArray().Len() != 1 {
+		t.Errorf("FILTER_FORCE_ARRAY wraps scalars, got %v", result)
+	}
+}
+
+func TestFilterVarUnknownFilter(t *testing.T) {
+	var warnings []string
+	f := &Filter{Warning: func(message string) { warnings = append(warnings, message) }}
+	result, err := f.Var(types.NewString("x"), types.NewInt(9999))
+	if err != nil || !result.IsBool() || result.ToBool() {
+		t.Errorf("unknown filter: got %v, %v", result, err)
+	}
+	if len(warnings) != 1 || warnings[0] != "filter_var(): Unknown filter with ID 9999" {
+		t.Errorf("warnings = %q", warnings)
+	}
+}
+
+func TestFilterVarCallback(t *testing.T) {
+	f := &Filter{Caller: stdlib.CallerFunc(func(callable *types.Value, args []*types.Value) (*types.Value, error) {
+		return types.NewString(strings.ToUpper(args[0].ToString())), nil
+	})}
+	def := types.NewEmptyArray()
+	def.Set(types.NewString("options"), types.NewString("strtoupper"))
+
+	result, err := f.Var(assoc("a", "x", "b", "y"), types.NewInt(FILTER_CALLBACK), types.NewArray(def))
+	if err != nil || get(result, "a").ToString() != "X" || get(result, "b").ToString() != "Y" {
+		t.Errorf("callbacks apply to each element: got %v, %v", result, err)
+	}
+
+	_, err = f.Var(types.NewString("x"), types.NewInt(FILTER_CALLBACK))
+	if e, ok := err.(*Error); !ok || e.Class != "TypeError" || e.Message != "filter_var(): Option must be a valid callback" {
+		t.Errorf("missing callback: got %v", err)
+	}
+}
+
+func TestFilterVarArrayDefinition(t *testing.T) {
+	input := assoc("id", "42", "email", "bad", "tags", assoc("x", "1", "y", "z"))
+	definition := assoc(
+		"id", FILTER_VALIDATE_INT,
+		"email", FILTER_VALIDATE_EMAIL,
+		"tags", assoc("filter", FILTER_VALIDATE_INT, "flags", FILTER_REQUIRE_ARRAY),
+		"missing", FILTER_DEFAULT,
+	)
+
+	result := FilterVarArray(input, definition)
+	if get(result, "id").ToInt() != 42 || !get(result, "email").IsBool() {
+		t.Errorf("per-key filters: got %v", result)
+	}
+	if tags := get(result, "tags"); get(tags, "x").ToInt() != 1 || !get(tags, "y").IsBool() {
+		t.Errorf("array entries: got %v", tags)
+	}
+	if missing := get(result, "missing"); missing == nil || !missing.IsNull() {
+		t.Errorf("missing keys are null, got %v", missing)
+	}
+
+	result = FilterVarArray(input, definition, types.NewBool(false))
+	if _, ok := result.ToArray().Get(types.NewString("missing")); ok {
+		t.Errorf("add_empty=false skips missing keys, got %v", result)
+	}
+
+	bad := types.NewEmptyArray()
+	bad.Set(types.NewInt(0), types.NewInt(FILTER_DEFAULT))
+	_, err := (&Filter{}).VarArray(input, types.NewArray(bad))
+	if e, ok := err.(*Error); !ok || e.Class != "TypeError" || e.Message != "filter_var_array(): Argument #2 ($options) must contain only string keys" {
+		t.Errorf("int keys: got %v", err)
+	}
+}
+
+func TestFilterInput(t *testing.T) {
+	f := &Filter{Source: func(source int64) *types.Array {
+		if source == INPUT_GET {
+			return assoc("page", "3", "q", "abc").ToArray()
+		}
+		return nil
+	}}
+
+	result, err := f.Input(types.NewInt(INPUT_GET), types.NewString("page"), types.NewInt(FILTER_VALIDATE_INT))
+	if err != nil || !result.IsInt() || result.ToInt() != 3 {
+		t.Errorf("filter_input(INPUT_GET, 'page'): got %v, %v", result, err)
+	}
+
+	result, _ = f.Input(types.NewInt(INPUT_GET), types.NewString("nope"), types.NewInt(FILTER_VALIDATE_INT))
+	if !result.IsNull() {
+		t.Errorf("missing variables are null, got %v", result)
+	}
+	result, _ = f.Input(types.NewInt(INPUT_GET), types.NewString("nope"), types.NewInt(FILTER_VALIDATE_INT), types.NewInt(FILTER_NULL_ON_FAILURE))
+	if !result.IsBool() || result.ToBool() {
+		t.Errorf("FILTER_NULL_ON_FAILURE makes missing variables false, got %v", result)
+	}
+	result, _ = f.Input(types.NewInt(INPUT_POST), types.NewString("page"), types.NewInt(FILTER_VALIDATE_INT),
+		options(0, map[string]*types.Value{"default": types.NewInt(1)}))
+	if result.ToInt() != 1 {
+		t.Errorf("missing variables take the default option, got %v", result)
+	}
+
+	if has, _ := f.HasVar(types.NewInt(INPUT_GET), types.NewString("q")); !has.ToBool() {
+		t.Errorf("filter_has_var(INPUT_GET, 'q') should be true")
+	}
+	if has, _ := f.HasVar(types.NewInt(INPUT_COOKIE), types.NewString("q")); has.ToBool() {
+		t.Errorf("filter_has_var(INPUT_COOKIE, 'q') should be false")
+	}
+
+	_, err = f.Input(types.NewInt(3), types.NewString("q"))
+	if e, ok := err.(*Error); !ok || e.Class != "ValueError" || e.Message != "filter_input(): Argument #1 ($type) must be an INPUT_* constant" {
+		t.Errorf("invalid input type: got %v", err)
+	}
+
+	result, _ = f.InputArray(types.NewInt(INPUT_GET), assoc("page", FILTER_VALIDATE_INT, "q", FILTER_VALIDATE_INT))
+	if get(result, "page").ToInt() != 3 || !get(result, "q").IsBool() {
+		t.Errorf("filter_input_array(INPUT_GET): got %v", result)
+	}
+	if result, _ := f.InputArray(types.NewInt(INPUT_POST)); !result.IsNull() {
+		t.Errorf("sources without data give null, got %v", result)
+	}
+}
+
+func TestFilterListAndID(t *testing.T) {
+	if n := FilterList().ToArray().Len(); n != len(filterNames) {
+		t.Errorf("filter_list() has %d names", n)
+	}
+	if id := FilterID(types.NewString("validate_email")); id.ToInt() != FILTER_VALIDATE_EMAIL {
+		t.Errorf("filter_id('validate_email') = %v", id)
+	}
+	if id := FilterID(types.NewString("nope")); !id.IsBool() {
+		t.Errorf("filter_id('nope') = %v, want false", id)
+	}
+	for _, filter := range filterNames {
+		if !knownFilter(filter.id) {
+			t.Errorf("filter %s (%d) has no implementation", filter.name, filter.id)
+		}
+	}
+}
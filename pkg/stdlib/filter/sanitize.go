@@ -0,0 +1,274 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	stdstring "github.com/krizos/php-go/pkg/stdlib/string"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Sanitization Functions
+// ============================================================================
+
+// sanitizer adapts a sanitizing function, which cannot fail, to a filter
+func sanitizer(sanitize func(str string, flags int64) string) filterFunc {
+	return func(r run, value string, flags int64, options *types.Value) (*types.Value, error) {
+		return types.NewString(sanitize(value, flags)), nil
+	}
+}
+
+// strip removes the bytes the FILTER_FLAG_STRIP_* flags select: below 32,
+// from 127 on, and backticks
+func strip(str string, flags int64) string {
+	if flags&(FILTER_FLAG_STRIP_LOW|FILTER_FLAG_STRIP_HIGH|FILTER_FLAG_STRIP_BACKTICK) == 0 {
+		return str
+	}
+	var result strings.Builder
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if (c < 32 && flags&FILTER_FLAG_STRIP_LOW != 0) ||
+			(c >= 127 && flags&FILTER_FLAG_STRIP_HIGH != 0) ||
+			(c == '`' && flags&FILTER_FLAG_STRIP_BACKTICK != 0) {
+			continue
+		}
+		result.WriteByte(c)
+	}
+	return result.String()
+}
+
+// encodeHTML encodes the bytes of a set as numeric entities, such as
+// "&#39;" for a quote
+func encodeHTML(str string, encode func(c byte) bool) string {
+	var result strings.Builder
+	for i := 0; i < len(str); i++ {
+		if encode(str[i]) {
+			fmt.Fprintf(&result, "&#%d;", str[i])
+		} else {
+			result.WriteByte(str[i])
+		}
+	}
+	return result.String()
+}
+
+// encodeFlags returns the bytes the FILTER_FLAG_ENCODE_* flags select,
+// plus those of always
+func encodeFlags(flags int64, always string) func(c byte) bool {
+	return func(c byte) bool {
+		return strings.IndexByte(always, c) >= 0 ||
+			(c == '&' && flags&FILTER_FLAG_ENCODE_AMP != 0) ||
+			(c < 32 && flags&FILTER_FLAG_ENCODE_LOW != 0) ||
+			(c >= 127 && flags&FILTER_FLAG_ENCODE_HIGH != 0)
+	}
+}
+
+// keep removes the bytes that are neither alphanumeric nor in allowed
+func keep(str, allowed string) string {
+	var result strings.Builder
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if isAlnum(c) || strings.IndexByte(allowed, c) >= 0 {
+			result.WriteByte(c)
+		}
+	}
+	return result.String()
+}
+
+// unsafeRaw returns the value unchanged unless flags ask to strip or
+// encode bytes, or to turn the empty string into null
+func unsafeRaw(r run, value string, flags int64, options *types.Value) (*types.Value, error) {
+	if value == "" && flags&FILTER_FLAG_EMPTY_STRING_NULL != 0 {
+		return types.NewNull(), nil
+	}
+	return types.NewString(encodeHTML(strip(value, flags), encodeFlags(flags, ""))), nil
+}
+
+// sanitizeString strips tags and encodes quotes (FILTER_SANITIZE_STRING,
+// deprecated since PHP 8.1)
+func sanitizeString(r run, value string, flags int64, options *types.Value) (*types.Value, error) {
+	always := "'\""
+	if flags&FILTER_FLAG_NO_ENCODE_QUOTES != 0 {
+		always = ""
+	}
+	result := stripTags(encodeHTML(strip(value, flags), encodeFlags(flags, always)))
+	if result == "" && flags&FILTER_FLAG_EMPTY_STRING_NULL != 0 {
+		return types.NewNull(), nil
+	}
+	return types.NewString(result), nil
+}
+
+// stripTags removes HTML tags and NUL bytes; a '<' followed by whitespace
+// does not start a tag
+func stripTags(str string) string {
+	var result strings.Builder
+	depth := 0
+	var quote byte
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		switch {
+		case depth > 0:
+			switch {
+			case quote != 0:
+				if c == quote {
+					quote = 0
+				}
+			case c == '"' || c == '\'':
+				quote = c
+			case c == '<':
+				depth++
+			case c == '>':
+				depth--
+			}
+		case c == '<' && (i+1 == len(str) || !isSpace(str[i+1])):
+			depth = 1
+		case c != 0:
+			result.WriteByte(c)
+		}
+	}
+	return result.String()
+}
+
+// sanitizeEncoded URL-encodes all bytes but letters, digits and "-._"
+func sanitizeEncoded(str string, flags int64) string {
+	const hex = "0123456789ABCDEF"
+	str = strip(str, flags)
+	var result strings.Builder
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if isAlnum(c) || c == '-' || c == '.' || c == '_' {
+			result.WriteByte(c)
+		} else {
+			result.WriteByte('%')
+			result.WriteByte(hex[c>>4])
+			result.WriteByte(hex[c&0xf])
+		}
+	}
+	return result.String()
+}
+
+// sanitizeSpecialChars encodes '"<>& and the bytes below 32 as numeric
+// entities
+func sanitizeSpecialChars(str string, flags int64) string {
+	encode := encodeFlags(flags, "'\"<>&")
+	return encodeHTML(strip(str, flags), func(c byte) bool { return c < 32 || encode(c) })
+}
+
+// sanitizeFullSpecialChars encodes the HTML special characters as named
+// entities like htmlspecialchars() with ENT_QUOTES, without encoding
+// existing entities again; quotes are kept with
+// FILTER_FLAG_NO_ENCODE_QUOTES. Invalid UTF-8 gives the empty string.
+func sanitizeFullSpecialChars(str string, flags int64) string {
+	if !utf8.ValidString(str) {
+		return ""
+	}
+	var result strings.Builder
+	for i := 0; i < len(str); i++ {
+		switch c := str[i]; {
+		case c == '&' && !startsEntity(str[i+1:]):
+			result.WriteString("&amp;")
+		case c == '<':
+			result.WriteString("&lt;")
+		case c == '>':
+			result.WriteString("&gt;")
+		case c == '"' && flags&FILTER_FLAG_NO_ENCODE_QUOTES == 0:
+			result.WriteString("&quot;")
+		case c == '\'' && flags&FILTER_FLAG_NO_ENCODE_QUOTES == 0:
+			result.WriteString("&#039;")
+		default:
+			result.WriteByte(c)
+		}
+	}
+	return result.String()
+}
+
+// startsEntity reports whether the text after a '&' completes an entity:
+// a name, "#" and decimal digits, or "#x" and hex digits, and a ';'
+func startsEntity(str string) bool {
+	end := strings.IndexByte(str, ';')
+	if end <= 0 {
+		return false
+	}
+	name := str[:end]
+	digits := func(s string, valid func(c byte) bool) bool {
+		if s == "" {
+			return false
+		}
+		for i := 0; i < len(s); i++ {
+			if !valid(s[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	switch {
+	case strings.HasPrefix(name, "#x") || strings.HasPrefix(name, "#X"):
+		return digits(name[2:], isHex)
+	case name[0] == '#':
+		return digits(name[1:], isDigit)
+	}
+	return digits(name, isAlnum)
+}
+
+// sanitizeEmail removes all bytes but letters, digits and
+// !#$%&'*+-=?^_`{|}~@.[]
+func sanitizeEmail(str string, flags int64) string {
+	return keep(str, "!#$%&'*+-=?^_`{|}~@.[]")
+}
+
+// sanitizeURL removes all bytes but letters, digits and
+// $-_.+!*'(),{}|\^~[]`<>#%";/?:@&=
+func sanitizeURL(str string, flags int64) string {
+	return keep(str, "$-_.+!*'(),{}|\\^~[]`<>#%\";/?:@&=")
+}
+
+// sanitizeNumberInt removes all bytes but digits, '+' and '-'
+func sanitizeNumberInt(str string, flags int64) string {
+	var result strings.Builder
+	for i := 0; i < len(str); i++ {
+		if c := str[i]; isDigit(c) || c == '+' || c == '-' {
+			result.WriteByte(c)
+		}
+	}
+	return result.String()
+}
+
+// sanitizeNumberFloat removes all bytes but digits, '+' and '-', and the
+// '.', ',' and 'e' the FILTER_FLAG_ALLOW_FRACTION, _THOUSAND and
+// _SCIENTIFIC flags allow
+func sanitizeNumberFloat(str string, flags int64) string {
+	var result strings.Builder
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if isDigit(c) || c == '+' || c == '-' ||
+			(c == '.' && flags&FILTER_FLAG_ALLOW_FRACTION != 0) ||
+			(c == ',' && flags&FILTER_FLAG_ALLOW_THOUSAND != 0) ||
+			((c == 'e' || c == 'E') && flags&FILTER_FLAG_ALLOW_SCIENTIFIC != 0) {
+			result.WriteByte(c)
+		}
+	}
+	return result.String()
+}
+
+// sanitizeAddSlashes escapes quotes, backslashes and NUL bytes like
+// addslashes()
+func sanitizeAddSlashes(str string, flags int64) string {
+	return stdstring.Addslashes(types.NewString(str)).ToString()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
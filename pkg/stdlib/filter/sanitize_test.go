@@ -0,0 +1,62 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestSanitizeFilters(t *testing.T) {
+	tests := []struct {
+		input  string
+		filter int64
+		flags  int64
+		want   string
+	}{
+		{"a\x01b\x80c`d", FILTER_UNSAFE_RAW, FILTER_FLAG_STRIP_LOW | FILTER_FLAG_STRIP_HIGH | FILTER_FLAG_STRIP_BACKTICK, "abcd"},
+		{"a&b\x01", FILTER_UNSAFE_RAW, FILTER_FLAG_ENCODE_AMP | FILTER_FLAG_ENCODE_LOW, "a&#38;b&#1;"},
+		{`<a href="x">"q"</a>`, FILTER_SANITIZE_STRING, 0, "&#34;q&#34;"},
+		{`"q" & 1 < 2`, FILTER_SANITIZE_STRING, FILTER_FLAG_NO_ENCODE_QUOTES, `"q" & 1 < 2`},
+		{"a b/c~", FILTER_SANITIZE_ENCODED, 0, "a%20b%2Fc%7E"},
+		{"<a href='x'>\t", FILTER_SANITIZE_SPECIAL_CHARS, 0, "&#60;a href=&#39;x&#39;&#62;&#9;"},
+		{`<b>"Tom" & 'Jerry' &amp;</b>`, FILTER_SANITIZE_FULL_SPECIAL_CHARS, 0, "&lt;b&gt;&quot;Tom&quot; &amp; &#039;Jerry&#039; &amp;&lt;/b&gt;"},
+		{`"q"`, FILTER_SANITIZE_FULL_SPECIAL_CHARS, FILTER_FLAG_NO_ENCODE_QUOTES, `"q"`},
+		{"(user)@[host].com", FILTER_SANITIZE_EMAIL, 0, "user@[host].com"},
+		{"http://exa mple.com/ä", FILTER_SANITIZE_URL, 0, "http://example.com/"},
+		{"-1,234.5e6", FILTER_SANITIZE_NUMBER_FLOAT, FILTER_FLAG_ALLOW_FRACTION, "-1234.56"},
+		{"-1,234.5e6", FILTER_SANITIZE_NUMBER_FLOAT, FILTER_FLAG_ALLOW_THOUSAND | FILTER_FLAG_ALLOW_SCIENTIFIC, "-1,2345e6"},
+		{`O'Re"il\ly`, FILTER_SANITIZE_ADD_SLASHES, 0, `O\'Re\"il\\ly`},
+	}
+	for _, tt := range tests {
+		result := FilterVar(types.NewString(tt.input), types.NewInt(tt.filter), types.NewInt(tt.flags))
+		if result.ToString() != tt.want {
+			t.Errorf("FilterVar(%q, %d, %d) = %q, want %q", tt.input, tt.filter, tt.flags, result.ToString(), tt.want)
+		}
+	}
+}
+
+func TestSanitizeEmptyStringNull(t *testing.T) {
+	for _, filter := range []int64{FILTER_UNSAFE_RAW, FILTER_SANITIZE_STRING} {
+		result := FilterVar(types.NewString(""), types.NewInt(filter), types.NewInt(FILTER_FLAG_EMPTY_STRING_NULL))
+		if !result.IsNull() {
+			t.Errorf("FilterVar('', %d, FILTER_FLAG_EMPTY_STRING_NULL) = %v, want null", filter, result)
+		}
+	}
+}
+
+func TestSanitizeConvertsScalars(t *testing.T) {
+	tests := []struct {
+		input *types.Value
+		want  string
+	}{
+		{types.NewInt(42), "42"},
+		{types.NewBool(true), "1"},
+		{types.NewNull(), ""},
+	}
+	for _, tt := range tests {
+		result := FilterVar(tt.input)
+		if !result.IsString() || result.ToString() != tt.want {
+			t.Errorf("FilterVar(%v) = %v, want %q", tt.input, result, tt.want)
+		}
+	}
+}
@@ -0,0 +1,471 @@
+package filter
+
+import (
+	"math"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/stdlib/pcre"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Validation Functions
+// ============================================================================
+
+// Validators return the validated value, converted for int, float and
+// boolean, or nil if validation fails.
+
+// trim removes the whitespace validators ignore around a value
+func trim(str string) string {
+	return strings.Trim(str, " \t\r\v\n")
+}
+
+// validateBoolean accepts "1", "true", "on" and "yes" as true and "0",
+// "false", "off", "no" and "" as false, ignoring case
+func validateBoolean(r run, value string, flags int64, options *types.Value) (*types.Value, error) {
+	switch strings.ToLower(trim(value)) {
+	case "1", "true", "on", "yes":
+		return types.NewBool(true), nil
+	case "0", "false", "off", "no", "":
+		return types.NewBool(false), nil
+	}
+	return nil, nil
+}
+
+// validateInt accepts decimal integers without leading zeros, and with
+// FILTER_FLAG_ALLOW_HEX or _OCTAL "0x" and "0" (or "0o") prefixed ones,
+// within the "min_range" and "max_range" options
+func validateInt(r run, value string, flags int64, options *types.Value) (*types.Value, error) {
+	str := trim(value)
+	if str == "" {
+		return nil, nil
+	}
+
+	var n int64
+	var ok bool
+	switch {
+	case str[0] != '0':
+		n, ok = parseDecimal(str)
+	case len(str) > 1 && (str[1] == 'x' || str[1] == 'X') && flags&FILTER_FLAG_ALLOW_HEX != 0:
+		n, ok = parseBase(str[2:], 16)
+		ok = ok && len(str) > 2
+	case flags&FILTER_FLAG_ALLOW_OCTAL != 0:
+		digits := str[1:]
+		if digits != "" && (digits[0] == 'o' || digits[0] == 'O') {
+			digits = digits[1:]
+			if digits == "" {
+				return nil, nil
+			}
+		}
+		n, ok = parseBase(digits, 8)
+	default:
+		ok = len(str) == 1
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	if min, set := option(options, "min_range"); set && n < min.ToInt() {
+		return nil, nil
+	}
+	if max, set := option(options, "max_range"); set && n > max.ToInt() {
+		return nil, nil
+	}
+	return types.NewInt(n), nil
+}
+
+// parseDecimal parses a signed decimal integer without leading zeros
+// ("+0" and "-0" aside); false if it is malformed or overflows
+func parseDecimal(str string) (int64, bool) {
+	negative := false
+	if str[0] == '+' || str[0] == '-' {
+		negative = str[0] == '-'
+		str = str[1:]
+	}
+	if str == "0" {
+		return 0, true
+	}
+	if str == "" || str[0] < '1' || str[0] > '9' {
+		return 0, false
+	}
+
+	// Accumulate negatively, which reaches math.MinInt64
+	n := int64(0)
+	for i := 0; i < len(str); i++ {
+		if !isDigit(str[i]) {
+			return 0, false
+		}
+		digit := int64(str[i] - '0')
+		if n < (math.MinInt64+digit)/10 {
+			return 0, false
+		}
+		n = n*10 - digit
+	}
+	if !negative {
+		if n == math.MinInt64 {
+			return 0, false
+		}
+		n = -n
+	}
+	return n, true
+}
+
+// parseBase parses unsigned hex or octal digits; false if they are
+// malformed or overflow
+func parseBase(digits string, base int64) (int64, bool) {
+	n := int64(0)
+	for i := 0; i < len(digits); i++ {
+		c := digits[i]
+		var digit int64
+		switch {
+		case c >= '0' && c <= '9':
+			digit = int64(c - '0')
+		case c >= 'a' && c <= 'f':
+			digit = int64(c-'a') + 10
+		case c >= 'A' && c <= 'F':
+			digit = int64(c-'A') + 10
+		default:
+			return 0, false
+		}
+		if digit >= base || n > (math.MaxInt64-digit)/base {
+			return 0, false
+		}
+		n = n*base + digit
+	}
+	return n, true
+}
+
+// validateFloat accepts decimal numbers with an optional exponent, the
+// decimal separator of the "decimal" option ('.' by default) and, with
+// FILTER_FLAG_ALLOW_THOUSAND, groups of three digits separated by one of
+// the "thousand" option's characters ("',." by default), within the
+// "min_range" and "max_range" options
+func validateFloat(r run, value string, flags int64, options *types.Value) (*types.Value, error) {
+	str := trim(value)
+	if str == "" {
+		return nil, nil
+	}
+
+	decimal := byte('.')
+	if option, set := option(options, "decimal"); set {
+		if len(option.ToString()) != 1 {
+			return nil, &Error{Class: "ValueError", Message: r.function + "(): \"decimal\" option must be one character long"}
+		}
+		decimal = option.ToString()[0]
+	}
+	thousand := "',."
+	if option, set := option(options, "thousand"); set {
+		if option.ToString() == "" {
+			return nil, &Error{Class: "ValueError", Message: r.function + "(): \"thousand\" option cannot be empty"}
+		}
+		thousand = option.ToString()
+	}
+
+	// Rewrite the number in PHP's syntax: no separators, '.' as decimal
+	var number strings.Builder
+	i := 0
+	if str[0] == '+' || str[0] == '-' {
+		number.WriteByte(str[0])
+		i++
+	}
+	digits := func() int {
+		start := i
+		for i < len(str) && isDigit(str[i]) {
+			number.WriteByte(str[i])
+			i++
+		}
+		return i - start
+	}
+	for first := true; ; first = false {
+		n := digits()
+		if i == len(str) || str[i] == decimal || str[i] == 'e' || str[i] == 'E' {
+			if !first && n != 3 {
+				return nil, nil
+			}
+			if i < len(str) && str[i] == decimal {
+				number.WriteByte('.')
+				i++
+				digits()
+			}
+			if i < len(str) && (str[i] == 'e' || str[i] == 'E') {
+				number.WriteByte(str[i])
+				i++
+				if i < len(str) && (str[i] == '+' || str[i] == '-') {
+					number.WriteByte(str[i])
+					i++
+				}
+				digits()
+			}
+			break
+		}
+		if flags&FILTER_FLAG_ALLOW_THOUSAND == 0 || strings.IndexByte(thousand, str[i]) < 0 {
+			return nil, nil
+		}
+		if (first && (n < 1 || n > 3)) || (!first && n != 3) {
+			return nil, nil
+		}
+		i++
+	}
+	if i != len(str) {
+		return nil, nil
+	}
+
+	parsed, kind := types.ParseNumeric(number.String())
+	if kind != types.Numeric {
+		return nil, nil
+	}
+	f := parsed.ToFloat()
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, nil
+	}
+	if min, set := option(options, "min_range"); set && f < min.ToFloat() {
+		return nil, nil
+	}
+	if max, set := option(options, "max_range"); set && f > max.ToFloat() {
+		return nil, nil
+	}
+	return types.NewFloat(f), nil
+}
+
+// validateRegexp accepts values matching the PCRE pattern of the
+// "regexp" option
+func validateRegexp(r run, value string, flags int64, options *types.Value) (*types.Value, error) {
+	pattern, set := option(options, "regexp")
+	if !set {
+		return nil, &Error{Class: "ValueError", Message: r.function + "(): \"regexp\" option missing"}
+	}
+	if matched, _ := pcre.PregMatch(pattern, types.NewString(value)); !matched.IsInt() || matched.ToInt() != 1 {
+		return nil, nil
+	}
+	return types.NewString(value), nil
+}
+
+// validateDomain accepts domain names of at most 253 characters with
+// labels of at most 63; with FILTER_FLAG_HOSTNAME labels must also
+// consist of letters, digits and inner hyphens
+func validateDomain(r run, value string, flags int64, options *types.Value) (*types.Value, error) {
+	if !isDomain(value, flags&FILTER_FLAG_HOSTNAME != 0) {
+		return nil, nil
+	}
+	return types.NewString(value), nil
+}
+
+// isDomain validates a domain name; a trailing dot is ignored
+func isDomain(domain string, hostname bool) bool {
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" || len(domain) > 253 || domain[0] == '.' {
+		return false
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		if !hostname {
+			continue
+		}
+		if !isAlnum(label[0]) || !isAlnum(label[len(label)-1]) {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			if !isAlnum(label[i]) && label[i] != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validateURL accepts URLs made of the characters FILTER_SANITIZE_URL
+// keeps, with a scheme and a host (except for mailto:, news: and file:
+// URLs); http and https hosts must be valid hostnames or IPv6
+// addresses. FILTER_FLAG_PATH_REQUIRED and _QUERY_REQUIRED require a
+// path and a query.
+func validateURL(r run, value string, flags int64, options *types.Value) (*types.Value, error) {
+	if sanitizeURL(value, flags) != value {
+		return nil, nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" {
+		return nil, nil
+	}
+
+	host := u.Hostname()
+	switch scheme := strings.ToLower(u.Scheme); {
+	case scheme == "http" || scheme == "https":
+		if host == "" {
+			return nil, nil
+		}
+		if strings.HasPrefix(u.Host, "[") {
+			if ip, err := netip.ParseAddr(host); err != nil || !ip.Is6() || ip.Zone() != "" {
+				return nil, nil
+			}
+		} else if !isDomain(host, true) {
+			return nil, nil
+		}
+	case host == "" && scheme != "mailto" && scheme != "news" && scheme != "file":
+		return nil, nil
+	}
+
+	if flags&FILTER_FLAG_PATH_REQUIRED != 0 && u.Path == "" {
+		return nil, nil
+	}
+	if flags&FILTER_FLAG_QUERY_REQUIRED != 0 && u.RawQuery == "" && !u.ForceQuery {
+		return nil, nil
+	}
+	return types.NewString(value), nil
+}
+
+// emailLocal and emailDomain match the parts of an email address the way
+// PHP's validation pattern does: dot-separated atoms or quoted strings,
+// and hostnames ending in a label that starts with a letter
+var (
+	emailLocal  = regexp.MustCompile(`^(?:[\x21\x23-\x27\x2A\x2B\x2D\x2F-\x39\x3D\x3F\x5E-\x7E]+|"(?:[\x01-\x08\x0B\x0C\x0E-\x1F\x21\x23-\x5B\x5D-\x7F]|\\[\x00-\x7F])*")(?:\.(?:[\x21\x23-\x27\x2A\x2B\x2D\x2F-\x39\x3D\x3F\x5E-\x7E]+|"(?:[\x01-\x08\x0B\x0C\x0E-\x1F\x21\x23-\x5B\x5D-\x7F]|\\[\x00-\x7F])*"))*$`)
+	emailDomain = regexp.MustCompile(`(?i)^(?:(?:xn--)?[a-z0-9]+(?:-+[a-z0-9]+)*\.)+(?:[a-z][a-z0-9]*|xn--[a-z0-9]+)(?:-+[a-z0-9]+)*$`)
+)
+
+// validateEmail accepts addresses of at most 254 characters whose local
+// part, of at most 64, is made of atoms or quoted strings and whose
+// domain is a hostname or a bracketed IPv4 or "IPv6:" address literal
+func validateEmail(r run, value string, flags int64, options *types.Value) (*types.Value, error) {
+	at := strings.LastIndexByte(value, '@')
+	if at < 0 || len(value) > 254 || at > 64 {
+		return nil, nil
+	}
+	local, domain := value[:at], value[at+1:]
+	if !emailLocal.MatchString(local) {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(domain, "[") && strings.HasSuffix(domain, "]") {
+		literal := domain[1 : len(domain)-1]
+		want6 := len(literal) > 5 && strings.EqualFold(literal[:5], "IPv6:")
+		if want6 {
+			literal = literal[5:]
+		}
+		ip, err := netip.ParseAddr(literal)
+		if err != nil || ip.Zone() != "" || ip.Is6() != want6 {
+			return nil, nil
+		}
+		return types.NewString(value), nil
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if len(label) > 63 {
+			return nil, nil
+		}
+	}
+	if !emailDomain.MatchString(domain) {
+		return nil, nil
+	}
+	return types.NewString(value), nil
+}
+
+// Address ranges of the FILTER_FLAG_NO_PRIV_RANGE, _NO_RES_RANGE and
+// _GLOBAL_RANGE flags
+var (
+	privateRanges = prefixes(
+		"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16",
+		"fc00::/7",
+	)
+	reservedRanges = prefixes(
+		"0.0.0.0/8", "127.0.0.0/8", "169.254.0.0/16", "240.0.0.0/4",
+		"::/128", "::1/128", "::ffff:0:0/96", "fe80::/10",
+	)
+	nonGlobalRanges = prefixes(
+		"100.64.0.0/10", "192.0.0.0/24", "192.0.2.0/24", "198.18.0.0/15", "198.51.100.0/24", "203.0.113.0/24",
+		"64:ff9b:1::/48", "100::/64", "2001::/23", "2001:db8::/32", "2002::/16",
+	)
+)
+
+func prefixes(ranges ...string) []netip.Prefix {
+	result := make([]netip.Prefix, len(ranges))
+	for i, r := range ranges {
+		result[i] = netip.MustParsePrefix(r)
+	}
+	return result
+}
+
+func inRanges(ip netip.Addr, ranges []netip.Prefix) bool {
+	for _, prefix := range ranges {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateIP accepts IPv4 addresses in dotted decimal without leading
+// zeros and IPv6 addresses, restricted to a version by FILTER_FLAG_IPV4
+// or _IPV6 and outside private, reserved or non-global ranges as the
+// flags ask
+func validateIP(r run, value string, flags int64, options *types.Value) (*types.Value, error) {
+	var v6 bool
+	switch {
+	case strings.IndexByte(value, ':') >= 0:
+		v6 = true
+	case strings.IndexByte(value, '.') < 0:
+		return nil, nil
+	}
+	only4, only6 := flags&FILTER_FLAG_IPV4 != 0, flags&FILTER_FLAG_IPV6 != 0
+	if only4 != only6 && only6 != v6 {
+		return nil, nil
+	}
+
+	ip, err := netip.ParseAddr(value)
+	if err != nil || ip.Zone() != "" || ip.Is6() != v6 {
+		return nil, nil
+	}
+	global := flags&FILTER_FLAG_GLOBAL_RANGE != 0
+	if (flags&FILTER_FLAG_NO_PRIV_RANGE != 0 || global) && inRanges(ip, privateRanges) {
+		return nil, nil
+	}
+	if (flags&FILTER_FLAG_NO_RES_RANGE != 0 || global) && inRanges(ip, reservedRanges) {
+		return nil, nil
+	}
+	if global && inRanges(ip, nonGlobalRanges) {
+		return nil, nil
+	}
+	return types.NewString(value), nil
+}
+
+// validateMAC accepts 48-bit MAC addresses as six pairs of hex digits
+// separated by '-' or ':', or three groups of four separated by '.'; the
+// "separator" option requires a separator
+func validateMAC(r run, value string, flags int64, options *types.Value) (*types.Value, error) {
+	var separator byte
+	if option, set := option(options, "separator"); set {
+		if len(option.ToString()) != 1 {
+			return nil, &Error{Class: "ValueError", Message: r.function + "(): \"separator\" option must be one character long"}
+		}
+		separator = option.ToString()[0]
+	}
+
+	var groups, length int
+	var found byte
+	switch {
+	case len(value) == 14:
+		groups, length, found = 3, 4, '.'
+	case len(value) == 17 && (value[2] == '-' || value[2] == ':'):
+		groups, length, found = 6, 2, value[2]
+	default:
+		return nil, nil
+	}
+	if separator != 0 && separator != found {
+		return nil, nil
+	}
+
+	for i := 0; i < groups; i++ {
+		offset := i * (length + 1)
+		if i < groups-1 && value[offset+length] != found {
+			return nil, nil
+		}
+		for _, c := range []byte(value[offset : offset+length]) {
+			if !isHex(c) {
+				return nil, nil
+			}
+		}
+	}
+	return types.NewString(value), nil
+}
@@ -0,0 +1,148 @@
+package hash
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"encoding/binary"
+	"hash"
+	"hash/adler32"
+	"hash/crc32"
+	"hash/fnv"
+	"strings"
+)
+
+// ============================================================================
+// Hash Algorithms
+// ============================================================================
+
+// algorithm is a hashing algorithm known by its PHP name. Checksums such
+// as crc32 are not cryptographic and cannot be used for HMAC.
+type algorithm struct {
+	name          string
+	new           func() hash.Hash
+	cryptographic bool
+}
+
+// algorithmList lists the algorithms in the order of hash_algos()
+var algorithmList = []algorithm{
+	{"md5", md5.New, true},
+	{"sha1", sha1.New, true},
+	{"sha224", sha256.New224, true},
+	{"sha256", sha256.New, true},
+	{"sha384", sha512.New384, true},
+	{"sha512/224", sha512.New512_224, true},
+	{"sha512/256", sha512.New512_256, true},
+	{"sha512", sha512.New, true},
+	{"sha3-224", func() hash.Hash { return sha3.New224() }, true},
+	{"sha3-256", func() hash.Hash { return sha3.New256() }, true},
+	{"sha3-384", func() hash.Hash { return sha3.New384() }, true},
+	{"sha3-512", func() hash.Hash { return sha3.New512() }, true},
+	{"adler32", func() hash.Hash { return adler32.New() }, false},
+	{"crc32", func() hash.Hash { return newCrc32Bzip2() }, false},
+	{"crc32b", func() hash.Hash { return crc32.NewIEEE() }, false},
+	{"crc32c", func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) }, false},
+	{"fnv132", func() hash.Hash { return fnv.New32() }, false},
+	{"fnv1a32", func() hash.Hash { return fnv.New32a() }, false},
+	{"fnv164", func() hash.Hash { return fnv.New64() }, false},
+	{"fnv1a64", func() hash.Hash { return fnv.New64a() }, false},
+	{"joaat", func() hash.Hash { return &joaat{} }, false},
+}
+
+// algorithms indexes algorithmList by name
+var algorithms = func() map[string]*algorithm {
+	m := make(map[string]*algorithm, len(algorithmList))
+	for i := range algorithmList {
+		m[algorithmList[i].name] = &algorithmList[i]
+	}
+	return m
+}()
+
+// lookupAlgorithm returns the algorithm with a case-insensitive name, or
+// nil if it is unknown
+func lookupAlgorithm(name string) *algorithm {
+	return algorithms[strings.ToLower(name)]
+}
+
+// ============================================================================
+// Checksums Without a Go Implementation
+// ============================================================================
+
+// crc32Bzip2 is PHP's "crc32" algorithm: the CRC-32 of bzip2, computed
+// MSB-first, whose digest PHP writes least significant byte first
+type crc32Bzip2 struct {
+	crc uint32
+}
+
+var crc32Bzip2Table = func() *[256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return &table
+}()
+
+func newCrc32Bzip2() *crc32Bzip2 {
+	return &crc32Bzip2{crc: 0xFFFFFFFF}
+}
+
+func (d *crc32Bzip2) Write(p []byte) (int, error) {
+	for _, b := range p {
+		d.crc = d.crc<<8 ^ crc32Bzip2Table[byte(d.crc>>24)^b]
+	}
+	return len(p), nil
+}
+
+func (d *crc32Bzip2) Sum(b []byte) []byte {
+	return binary.LittleEndian.AppendUint32(b, ^d.crc)
+}
+
+func (d *crc32Bzip2) Reset()         { d.crc = 0xFFFFFFFF }
+func (d *crc32Bzip2) Size() int      { return 4 }
+func (d *crc32Bzip2) BlockSize() int { return 1 }
+
+func (d *crc32Bzip2) Clone() (hash.Cloner, error) {
+	c := *d
+	return &c, nil
+}
+
+// joaat is Bob Jenkins' one-at-a-time hash
+type joaat struct {
+	h uint32
+}
+
+func (d *joaat) Write(p []byte) (int, error) {
+	for _, b := range p {
+		d.h += uint32(b)
+		d.h += d.h << 10
+		d.h ^= d.h >> 6
+	}
+	return len(p), nil
+}
+
+func (d *joaat) Sum(b []byte) []byte {
+	h := d.h
+	h += h << 3
+	h ^= h >> 11
+	h += h << 15
+	return binary.BigEndian.AppendUint32(b, h)
+}
+
+func (d *joaat) Reset()         { d.h = 0 }
+func (d *joaat) Size() int      { return 4 }
+func (d *joaat) BlockSize() int { return 4 }
+
+func (d *joaat) Clone() (hash.Cloner, error) {
+	c := *d
+	return &c, nil
+}
@@ -0,0 +1,109 @@
+package hash
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Incremental Hashing
+// ============================================================================
+
+// HASH_HMAC is the hash_init() flag requesting an HMAC context
+const HASH_HMAC = 1
+
+// Context is the state of an incremental hash, held by a HashContext
+// object between hash_init() and hash_final()
+type Context struct {
+	Algo      string
+	hash      hash.Hash
+	finalized bool
+}
+
+// finalizedError is the TypeError of using a context after hash_final()
+func finalizedError(fn string) error {
+	return &Error{Class: "TypeError", Message: fmt.Sprintf("%s(): Argument #1 ($context) must be a valid, non-finalized HashContext", fn)}
+}
+
+// HashInit starts an incremental hash, an HMAC if flags has HASH_HMAC
+// hash_init(string $algo, int $flags = 0, string $key = ""): HashContext
+func HashInit(algo *types.Value, args ...*types.Value) (*Context, error) {
+	flags := int64(0)
+	if len(args) > 0 && args[0] != nil {
+		flags = args[0].ToInt()
+	}
+	key := ""
+	if len(args) > 1 && args[1] != nil {
+		key = args[1].ToString()
+	}
+
+	alg, err := hashAlgorithm("hash_init", algo, false)
+	if err != nil {
+		return nil, err
+	}
+	if flags&HASH_HMAC == 0 {
+		return &Context{Algo: alg.name, hash: alg.new()}, nil
+	}
+	if !alg.cryptographic {
+		return nil, valueError("hash_init(): Argument #1 ($algo) must be a cryptographic hashing algorithm if HMAC is requested")
+	}
+	if key == "" {
+		return nil, valueError("hash_init(): Argument #3 ($key) cannot be empty when HMAC is requested")
+	}
+	return &Context{Algo: alg.name, hash: hmac.New(alg.new, []byte(key))}, nil
+}
+
+// Update adds data to the hash
+// hash_update(HashContext $context, string $data): true
+func (c *Context) Update(data string) error {
+	if c.finalized {
+		return finalizedError("hash_update")
+	}
+	c.hash.Write([]byte(data))
+	return nil
+}
+
+// UpdateFile adds the contents of a file to the hash, reporting false if
+// it cannot be read
+// hash_update_file(HashContext $context, string $filename): bool
+func (c *Context) UpdateFile(filename *types.Value) (*types.Value, error) {
+	if c.finalized {
+		return nil, finalizedError("hash_update_file")
+	}
+	return types.NewBool(hashFile(c.hash, filename)), nil
+}
+
+// Final returns the digest and finalizes the context
+// hash_final(HashContext $context, bool $binary = false): string
+func (c *Context) Final(binary bool) (*types.Value, error) {
+	if c.finalized {
+		return nil, finalizedError("hash_final")
+	}
+	c.finalized = true
+	sum := c.hash.Sum(nil)
+	if binary {
+		return types.NewString(string(sum)), nil
+	}
+	return types.NewString(hex.EncodeToString(sum)), nil
+}
+
+// Copy returns an independent copy of the context
+// hash_copy(HashContext $context): HashContext
+func (c *Context) Copy() (*Context, error) {
+	if c.finalized {
+		return nil, finalizedError("hash_copy")
+	}
+	cloner, ok := c.hash.(hash.Cloner)
+	if !ok {
+		return nil, &Error{Class: "Error", Message: fmt.Sprintf("Cannot copy the state of %s", c.Algo)}
+	}
+	h, err := cloner.Clone()
+	if err != nil {
+		return nil, &Error{Class: "Error", Message: fmt.Sprintf("Cannot copy the state of %s: %v", c.Algo, err)}
+	}
+	return &Context{Algo: c.Algo, hash: h}, nil
+}
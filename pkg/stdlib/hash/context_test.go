@@ -0,0 +1,69 @@
+package hash
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestContextIncremental(t *testing.T) {
+	ctx, err := HashInit(types.NewString("sha256"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.Update("hel")
+	ctx.Update("lo")
+	result, err := ctx.Final(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := Hash(types.NewString("sha256"), types.NewString("hello"))
+	if result.ToString() != expected.ToString() {
+		t.Errorf("incremental sha256 = %s, want %s", result.ToString(), expected.ToString())
+	}
+
+	if err := ctx.Update("more"); err == nil {
+		t.Errorf("Update() after Final() should fail")
+	}
+}
+
+func TestContextHmac(t *testing.T) {
+	ctx, err := HashInit(types.NewString("md5"), types.NewInt(HASH_HMAC), types.NewString("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.Update("message")
+	result, _ := ctx.Final(false)
+	expected, _ := HashHmac(types.NewString("md5"), types.NewString("message"), types.NewString("secret"))
+	if result.ToString() != expected.ToString() {
+		t.Errorf("incremental HMAC = %s, want %s", result.ToString(), expected.ToString())
+	}
+
+	if _, err := HashInit(types.NewString("md5"), types.NewInt(HASH_HMAC)); err == nil {
+		t.Errorf("HashInit() with HASH_HMAC and no key should fail")
+	}
+	if _, err := HashInit(types.NewString("adler32"), types.NewInt(HASH_HMAC), types.NewString("k")); err == nil {
+		t.Errorf("HashInit() with HASH_HMAC and a checksum should fail")
+	}
+}
+
+func TestContextCopy(t *testing.T) {
+	for _, algo := range []string{"sha1", "crc32", "joaat"} {
+		ctx, _ := HashInit(types.NewString(algo))
+		ctx.Update("abc")
+		copied, err := ctx.Copy()
+		if err != nil {
+			t.Fatalf("%s: Copy() error: %v", algo, err)
+		}
+		copied.Update("def")
+
+		original, _ := ctx.Final(false)
+		if want, _ := Hash(types.NewString(algo), types.NewString("abc")); original.ToString() != want.ToString() {
+			t.Errorf("%s: original = %s, want %s", algo, original.ToString(), want.ToString())
+		}
+		extended, _ := copied.Final(false)
+		if want, _ := Hash(types.NewString(algo), types.NewString("abcdef")); extended.ToString() != want.ToString() {
+			t.Errorf("%s: copy = %s, want %s", algo, extended.ToString(), want.ToString())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package hash
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Binary Encodings
+// ============================================================================
+
+// Base64Encode encodes data with MIME base64
+// base64_encode(string $string): string
+func Base64Encode(str *types.Value) *types.Value {
+	return types.NewString(base64.StdEncoding.EncodeToString([]byte(str.ToString())))
+}
+
+// Base64Decode decodes base64 data. Characters outside the alphabet are
+// skipped and padding is optional, unless strict is set, in which case
+// they make the result false.
+// base64_decode(string $string, bool $strict = false): string|false
+func Base64Decode(str *types.Value, strict ...*types.Value) *types.Value {
+	isStrict := len(strict) > 0 && strict[0] != nil && strict[0].ToBool()
+	s := str.ToString()
+
+	var data strings.Builder
+	padding := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '=':
+			padding++
+		case isBase64Char(c):
+			if padding > 0 && isStrict {
+				// Data after the padding
+				return types.NewBool(false)
+			}
+			data.WriteByte(c)
+		case isStrict && !isSpace(c):
+			return types.NewBool(false)
+		}
+	}
+
+	encoded := data.String()
+	if len(encoded)%4 == 1 {
+		if isStrict {
+			return types.NewBool(false)
+		}
+		// A single trailing character carries no complete byte
+		encoded = encoded[:len(encoded)-1]
+	}
+	if isStrict && padding > 0 && (len(encoded)+padding)%4 != 0 {
+		return types.NewBool(false)
+	}
+
+	decoded, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil && isStrict {
+		return types.NewBool(false)
+	}
+	return types.NewString(string(decoded))
+}
+
+func isBase64Char(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '+' || c == '/'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// Bin2hex converts binary data into its hexadecimal representation
+// bin2hex(string $string): string
+func Bin2hex(str *types.Value) *types.Value {
+	return types.NewString(hex.EncodeToString([]byte(str.ToString())))
+}
+
+// Hex2bin decodes a hexadecimally encoded binary string, warning about odd
+// lengths and invalid input
+// hex2bin(string $string): string|false
+func Hex2bin(str *types.Value) (*types.Value, string) {
+	s := str.ToString()
+	if len(s)%2 != 0 {
+		return types.NewBool(false), "Hexadecimal input string must have an even length"
+	}
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		return types.NewBool(false), "Input string must be hexadecimal string"
+	}
+	return types.NewString(string(decoded)), ""
+}
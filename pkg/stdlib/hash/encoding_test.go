@@ -0,0 +1,56 @@
+package hash
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestBase64(t *testing.T) {
+	if result := Base64Encode(types.NewString("This is an encoded string")); result.ToString() != "VGhpcyBpcyBhbiBlbmNvZGVkIHN0cmluZw==" {
+		t.Errorf("Base64Encode() = %s", result.ToString())
+	}
+
+	tests := []struct {
+		input    string
+		strict   bool
+		expected string
+		ok       bool
+	}{
+		{"VGhpcyBpcyBhbiBlbmNvZGVkIHN0cmluZw==", false, "This is an encoded string", true},
+		{"VGhpcyBpcyBhbiBlbmNvZGVkIHN0cmluZw", false, "This is an encoded string", true},
+		{"VGhp cyBp\ncw==", true, "This is", true},
+		{"VGhp#cw==", false, "This", true},
+		{"VGhp#cw==", true, "", false},
+		{"VGhpcw=x", true, "", false},
+		{"VGhpc", true, "", false},
+	}
+
+	for _, tt := range tests {
+		result := Base64Decode(types.NewString(tt.input), types.NewBool(tt.strict))
+		if !tt.ok {
+			if result.Type() != types.TypeBool || result.ToBool() {
+				t.Errorf("Base64Decode(%q, %v) = %q, want false", tt.input, tt.strict, result.ToString())
+			}
+			continue
+		}
+		if result.ToString() != tt.expected {
+			t.Errorf("Base64Decode(%q, %v) = %q, want %q", tt.input, tt.strict, result.ToString(), tt.expected)
+		}
+	}
+}
+
+func TestHex(t *testing.T) {
+	if result := Bin2hex(types.NewString("abc\x00\xff")); result.ToString() != "61626300ff" {
+		t.Errorf("Bin2hex() = %s", result.ToString())
+	}
+	if result, warning := Hex2bin(types.NewString("6578616d706C65")); result.ToString() != "example" || warning != "" {
+		t.Errorf("Hex2bin() = %q, %q", result.ToString(), warning)
+	}
+	if result, warning := Hex2bin(types.NewString("abc")); result.ToBool() || warning == "" {
+		t.Errorf("Hex2bin() of an odd length should fail with a warning")
+	}
+	if result, warning := Hex2bin(types.NewString("zz")); result.ToBool() || warning == "" {
+		t.Errorf("Hex2bin() of invalid hexits should fail with a warning")
+	}
+}
@@ -3,175 +3,122 @@ package hash
 import (
 	"crypto/hmac"
 	"crypto/md5"
+	"crypto/pbkdf2"
 	"crypto/sha1"
-	"crypto/sha256"
-	"crypto/sha512"
 	"encoding/hex"
+	"fmt"
 	"hash"
+	"hash/crc32"
 	"io"
 	"os"
-	"strings"
 
 	"github.com/krizos/php-go/pkg/types"
 )
 
-// ============================================================================
-// Hash Algorithms Map
-// ============================================================================
-
-// getHashAlgorithm returns the hash.Hash for the given algorithm name
-func getHashAlgorithm(algo string) hash.Hash {
-	algo = strings.ToLower(algo)
-
-	switch algo {
-	case "md5":
-		return md5.New()
-	case "sha1":
-		return sha1.New()
-	case "sha256":
-		return sha256.New()
-	case "sha224":
-		return sha256.New224()
-	case "sha384":
-		return sha512.New384()
-	case "sha512":
-		return sha512.New()
-	case "sha512/224":
-		return sha512.New512_224()
-	case "sha512/256":
-		return sha512.New512_256()
-	default:
-		return nil
-	}
+// Error is an error thrown by a hash function, such as the ValueError for
+// an unknown algorithm
+type Error struct {
+	Class   string
+	Message string
 }
 
-// ============================================================================
-// Hash Functions
-// ============================================================================
+func (e *Error) Error() string {
+	return e.Message
+}
 
-// Hash generates a hash value (message digest)
-// hash(string $algo, string $data, bool $binary = false): string
-func Hash(algo, data *types.Value, binary ...*types.Value) *types.Value {
-	h := getHashAlgorithm(algo.ToString())
-	if h == nil {
-		// Unknown algorithm
-		return types.NewBool(false)
-	}
+func valueError(format string, args ...interface{}) error {
+	return &Error{Class: "ValueError", Message: fmt.Sprintf(format, args...)}
+}
 
-	rawBinary := false
-	if len(binary) > 0 && binary[0] != nil {
-		rawBinary = binary[0].ToBool()
+// hashAlgorithm returns the algorithm for the $algo argument of fn; with
+// hmac set, only cryptographic algorithms are accepted
+func hashAlgorithm(fn string, algo *types.Value, hmac bool) (*algorithm, error) {
+	alg := lookupAlgorithm(algo.ToString())
+	if hmac && (alg == nil || !alg.cryptographic) {
+		return nil, valueError("%s(): Argument #1 ($algo) must be a valid cryptographic hashing algorithm", fn)
 	}
-
-	// Compute hash
-	h.Write([]byte(data.ToString()))
-	hashBytes := h.Sum(nil)
-
-	if rawBinary {
-		return types.NewString(string(hashBytes))
+	if alg == nil {
+		return nil, valueError("%s(): Argument #1 ($algo) must be a valid hashing algorithm", fn)
 	}
-
-	return types.NewString(hex.EncodeToString(hashBytes))
+	return alg, nil
 }
 
-// HashFile generates a hash value for a file
-// hash_file(string $algo, string $filename, bool $binary = false): string|false
-func HashFile(algo, filename *types.Value, binary ...*types.Value) *types.Value {
-	h := getHashAlgorithm(algo.ToString())
-	if h == nil {
-		return types.NewBool(false)
-	}
-
-	rawBinary := false
-	if len(binary) > 0 && binary[0] != nil {
-		rawBinary = binary[0].ToBool()
+// digest returns the sum of h as raw bytes or as lowercase hexits
+func digest(h hash.Hash, binary []*types.Value) *types.Value {
+	sum := h.Sum(nil)
+	if len(binary) > 0 && binary[0] != nil && binary[0].ToBool() {
+		return types.NewString(string(sum))
 	}
+	return types.NewString(hex.EncodeToString(sum))
+}
 
-	// Read file
+// hashFile writes the contents of a file to h, reporting false if it
+// cannot be read
+func hashFile(h hash.Hash, filename *types.Value) bool {
 	file, err := os.Open(filename.ToString())
 	if err != nil {
-		return types.NewBool(false)
+		return false
 	}
 	defer file.Close()
 
-	// Compute hash
-	if _, err := io.Copy(h, file); err != nil {
-		return types.NewBool(false)
-	}
+	_, err = io.Copy(h, file)
+	return err == nil
+}
 
-	hashBytes := h.Sum(nil)
+// ============================================================================
+// Hash Functions
+// ============================================================================
 
-	if rawBinary {
-		return types.NewString(string(hashBytes))
+// Hash generates a hash value (message digest)
+// hash(string $algo, string $data, bool $binary = false): string
+func Hash(algo, data *types.Value, binary ...*types.Value) (*types.Value, error) {
+	alg, err := hashAlgorithm("hash", algo, false)
+	if err != nil {
+		return nil, err
 	}
+	h := alg.new()
+	h.Write([]byte(data.ToString()))
+	return digest(h, binary), nil
+}
 
-	return types.NewString(hex.EncodeToString(hashBytes))
+// HashFile generates a hash value for a file
+// hash_file(string $algo, string $filename, bool $binary = false): string|false
+func HashFile(algo, filename *types.Value, binary ...*types.Value) (*types.Value, error) {
+	alg, err := hashAlgorithm("hash_file", algo, false)
+	if err != nil {
+		return nil, err
+	}
+	h := alg.new()
+	if !hashFile(h, filename) {
+		return types.NewBool(false), nil
+	}
+	return digest(h, binary), nil
 }
 
 // HashHmac generates a keyed hash value using HMAC
 // hash_hmac(string $algo, string $data, string $key, bool $binary = false): string
-func HashHmac(algo, data, key *types.Value, binary ...*types.Value) *types.Value {
-	h := getHashAlgorithm(algo.ToString())
-	if h == nil {
-		return types.NewBool(false)
-	}
-
-	rawBinary := false
-	if len(binary) > 0 && binary[0] != nil {
-		rawBinary = binary[0].ToBool()
+func HashHmac(algo, data, key *types.Value, binary ...*types.Value) (*types.Value, error) {
+	alg, err := hashAlgorithm("hash_hmac", algo, true)
+	if err != nil {
+		return nil, err
 	}
-
-	// Create HMAC
-	mac := hmac.New(func() hash.Hash {
-		return getHashAlgorithm(algo.ToString())
-	}, []byte(key.ToString()))
-
+	mac := hmac.New(alg.new, []byte(key.ToString()))
 	mac.Write([]byte(data.ToString()))
-	hashBytes := mac.Sum(nil)
-
-	if rawBinary {
-		return types.NewString(string(hashBytes))
-	}
-
-	return types.NewString(hex.EncodeToString(hashBytes))
+	return digest(mac, binary), nil
 }
 
 // HashHmacFile generates a keyed hash value for a file using HMAC
 // hash_hmac_file(string $algo, string $filename, string $key, bool $binary = false): string|false
-func HashHmacFile(algo, filename, key *types.Value, binary ...*types.Value) *types.Value {
-	h := getHashAlgorithm(algo.ToString())
-	if h == nil {
-		return types.NewBool(false)
-	}
-
-	rawBinary := false
-	if len(binary) > 0 && binary[0] != nil {
-		rawBinary = binary[0].ToBool()
-	}
-
-	// Read file
-	file, err := os.Open(filename.ToString())
+func HashHmacFile(algo, filename, key *types.Value, binary ...*types.Value) (*types.Value, error) {
+	alg, err := hashAlgorithm("hash_hmac_file", algo, true)
 	if err != nil {
-		return types.NewBool(false)
+		return nil, err
 	}
-	defer file.Close()
-
-	// Create HMAC
-	mac := hmac.New(func() hash.Hash {
-		return getHashAlgorithm(algo.ToString())
-	}, []byte(key.ToString()))
-
-	if _, err := io.Copy(mac, file); err != nil {
-		return types.NewBool(false)
+	mac := hmac.New(alg.new, []byte(key.ToString()))
+	if !hashFile(mac, filename) {
+		return types.NewBool(false), nil
 	}
-
-	hashBytes := mac.Sum(nil)
-
-	if rawBinary {
-		return types.NewString(string(hashBytes))
-	}
-
-	return types.NewString(hex.EncodeToString(hashBytes))
+	return digest(mac, binary), nil
 }
 
 // ============================================================================
@@ -181,95 +128,37 @@ func HashHmacFile(algo, filename, key *types.Value, binary ...*types.Value) *typ
 // Md5 calculates the MD5 hash of a string
 // md5(string $str, bool $binary = false): string
 func Md5(str *types.Value, binary ...*types.Value) *types.Value {
-	rawBinary := false
-	if len(binary) > 0 && binary[0] != nil {
-		rawBinary = binary[0].ToBool()
-	}
-
 	h := md5.New()
 	h.Write([]byte(str.ToString()))
-	hashBytes := h.Sum(nil)
-
-	if rawBinary {
-		return types.NewString(string(hashBytes))
-	}
-
-	return types.NewString(hex.EncodeToString(hashBytes))
+	return digest(h, binary)
 }
 
 // Md5File calculates the MD5 hash of a file
 // md5_file(string $filename, bool $binary = false): string|false
 func Md5File(filename *types.Value, binary ...*types.Value) *types.Value {
-	rawBinary := false
-	if len(binary) > 0 && binary[0] != nil {
-		rawBinary = binary[0].ToBool()
-	}
-
-	file, err := os.Open(filename.ToString())
-	if err != nil {
-		return types.NewBool(false)
-	}
-	defer file.Close()
-
 	h := md5.New()
-	if _, err := io.Copy(h, file); err != nil {
+	if !hashFile(h, filename) {
 		return types.NewBool(false)
 	}
-
-	hashBytes := h.Sum(nil)
-
-	if rawBinary {
-		return types.NewString(string(hashBytes))
-	}
-
-	return types.NewString(hex.EncodeToString(hashBytes))
+	return digest(h, binary)
 }
 
 // Sha1 calculates the SHA1 hash of a string
 // sha1(string $str, bool $binary = false): string
 func Sha1(str *types.Value, binary ...*types.Value) *types.Value {
-	rawBinary := false
-	if len(binary) > 0 && binary[0] != nil {
-		rawBinary = binary[0].ToBool()
-	}
-
 	h := sha1.New()
 	h.Write([]byte(str.ToString()))
-	hashBytes := h.Sum(nil)
-
-	if rawBinary {
-		return types.NewString(string(hashBytes))
-	}
-
-	return types.NewString(hex.EncodeToString(hashBytes))
+	return digest(h, binary)
 }
 
 // Sha1File calculates the SHA1 hash of a file
 // sha1_file(string $filename, bool $binary = false): string|false
 func Sha1File(filename *types.Value, binary ...*types.Value) *types.Value {
-	rawBinary := false
-	if len(binary) > 0 && binary[0] != nil {
-		rawBinary = binary[0].ToBool()
-	}
-
-	file, err := os.Open(filename.ToString())
-	if err != nil {
-		return types.NewBool(false)
-	}
-	defer file.Close()
-
 	h := sha1.New()
-	if _, err := io.Copy(h, file); err != nil {
+	if !hashFile(h, filename) {
 		return types.NewBool(false)
 	}
-
-	hashBytes := h.Sum(nil)
-
-	if rawBinary {
-		return types.NewString(string(hashBytes))
-	}
-
-	return types.NewString(hex.EncodeToString(hashBytes))
+	return digest(h, binary)
 }
 
 // ============================================================================
@@ -303,66 +192,75 @@ func HashEquals(knownString, userString *types.Value) *types.Value {
 // HashAlgos returns a list of registered hashing algorithms
 // hash_algos(): array
 func HashAlgos() *types.Value {
-	algos := []string{
-		"md5",
-		"sha1",
-		"sha224",
-		"sha256",
-		"sha384",
-		"sha512",
-		"sha512/224",
-		"sha512/256",
-	}
-
 	arr := types.NewEmptyArray()
-	for _, algo := range algos {
-		arr.Append(types.NewString(algo))
+	for _, alg := range algorithmList {
+		arr.Append(types.NewString(alg.name))
 	}
-
 	return types.NewArray(arr)
 }
 
-// HashHmacAlgos returns a list of registered hashing algorithms suitable for hash_hmac
+// HashHmacAlgos returns the algorithms suitable for hash_hmac(), leaving
+// out the non-cryptographic checksums
 // hash_hmac_algos(): array
 func HashHmacAlgos() *types.Value {
-	// Same as hash_algos for our implementation
-	return HashAlgos()
+	arr := types.NewEmptyArray()
+	for _, alg := range algorithmList {
+		if alg.cryptographic {
+			arr.Append(types.NewString(alg.name))
+		}
+	}
+	return types.NewArray(arr)
 }
 
 // ============================================================================
 // Additional Hash Functions
 // ============================================================================
 
-// Crc32 calculates the CRC32 polynomial of a string
+// Crc32 calculates the CRC32 polynomial of a string, the checksum of the
+// "crc32b" algorithm
 // crc32(string $str): int
 func Crc32(str *types.Value) *types.Value {
-	// Note: PHP's crc32 uses a different polynomial than Go's hash/crc32
-	// This is a simplified implementation
-	// For full compatibility, we'd need to match PHP's exact algorithm
-	data := []byte(str.ToString())
-
-	// Simple CRC32 implementation (not the same as PHP's)
-	// In production, would need exact PHP compatibility
-	var crc uint32 = 0xFFFFFFFF
-	for _, b := range data {
-		crc ^= uint32(b)
-		for j := 0; j < 8; j++ {
-			if crc&1 != 0 {
-				crc = (crc >> 1) ^ 0xEDB88320
-			} else {
-				crc >>= 1
-			}
-		}
-	}
-
-	return types.NewInt(int64(^crc))
+	return types.NewInt(int64(crc32.ChecksumIEEE([]byte(str.ToString()))))
 }
 
-// HashPbkdf2 generates a PBKDF2 key derivation of a password
+// HashPbkdf2 generates a PBKDF2 key derivation of a password. length
+// counts hexits unless binary is set, and defaults to the full digest.
 // hash_pbkdf2(string $algo, string $password, string $salt, int $iterations, int $length = 0, bool $binary = false): string
-func HashPbkdf2(algo, password, salt, iterations *types.Value, length ...*types.Value) *types.Value {
-	// This would require implementing PBKDF2
-	// For now, return a placeholder
-	// TODO: Implement full PBKDF2 support
-	return types.NewBool(false)
+func HashPbkdf2(algo, password, salt, iterations *types.Value, args ...*types.Value) (*types.Value, error) {
+	alg, err := hashAlgorithm("hash_pbkdf2", algo, true)
+	if err != nil {
+		return nil, err
+	}
+	iter := iterations.ToInt()
+	if iter <= 0 {
+		return nil, valueError("hash_pbkdf2(): Argument #4 ($iterations) must be greater than 0")
+	}
+	length := int64(0)
+	if len(args) > 0 && args[0] != nil {
+		length = args[0].ToInt()
+	}
+	if length < 0 {
+		return nil, valueError("hash_pbkdf2(): Argument #5 ($length) must be greater than or equal to 0")
+	}
+	binary := len(args) > 1 && args[1] != nil && args[1].ToBool()
+
+	if length == 0 {
+		length = int64(alg.new().Size())
+		if !binary {
+			length *= 2
+		}
+	}
+	keyLength := length
+	if !binary {
+		keyLength = (length + 1) / 2
+	}
+
+	key, err := pbkdf2.Key(alg.new, password.ToString(), []byte(salt.ToString()), int(iter), int(keyLength))
+	if err != nil {
+		return nil, valueError("hash_pbkdf2(): %v", err)
+	}
+	if binary {
+		return types.NewString(string(key)), nil
+	}
+	return types.NewString(hex.EncodeToString(key)[:length]), nil
 }
@@ -0,0 +1,280 @@
+package hash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ============================================================================
+// Password Hashing
+// ============================================================================
+
+// Password hashing algorithms, the values of the PASSWORD_* constants
+const (
+	PASSWORD_BCRYPT   = "2y"
+	PASSWORD_ARGON2I  = "argon2i"
+	PASSWORD_ARGON2ID = "argon2id"
+	PASSWORD_DEFAULT  = PASSWORD_BCRYPT
+)
+
+// Default costs of the password hashing algorithms
+const (
+	PASSWORD_BCRYPT_DEFAULT_COST        = 12
+	PASSWORD_ARGON2_DEFAULT_MEMORY_COST = 65536
+	PASSWORD_ARGON2_DEFAULT_TIME_COST   = 4
+	PASSWORD_ARGON2_DEFAULT_THREADS     = 1
+)
+
+// Sizes of the salt and the hash of argon2 password hashes
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// passwordHash is a parsed password hash with the options it was made
+// with; algo is empty for hashes of unknown algorithms
+type passwordHash struct {
+	algo    string
+	cost    int
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// parsePasswordHash recognizes bcrypt and argon2 hashes
+func parsePasswordHash(hash string) passwordHash {
+	if len(hash) == 60 && (strings.HasPrefix(hash, "$2y$") || strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$")) {
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return passwordHash{}
+		}
+		return passwordHash{algo: PASSWORD_BCRYPT, cost: cost}
+	}
+
+	// $argon2id$v=19$m=65536,t=4,p=1$<salt>$<hash>
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || (parts[1] != PASSWORD_ARGON2I && parts[1] != PASSWORD_ARGON2ID) {
+		return passwordHash{}
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return passwordHash{}
+	}
+	p := passwordHash{algo: parts[1]}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return passwordHash{}
+	}
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return passwordHash{}
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return passwordHash{}
+	}
+	return p
+}
+
+// argon2Key derives an argon2i or argon2id key
+func argon2Key(algo, password string, salt []byte, time, memory uint32, threads uint8, keyLength uint32) []byte {
+	if algo == PASSWORD_ARGON2I {
+		return argon2.Key([]byte(password), salt, time, memory, threads, keyLength)
+	}
+	return argon2.IDKey([]byte(password), salt, time, memory, threads, keyLength)
+}
+
+// passwordAlgo returns the algorithm of a $algo argument, null meaning the
+// default one
+func passwordAlgo(fn string, algo *types.Value) (string, error) {
+	if algo == nil || algo.IsNull() {
+		return PASSWORD_DEFAULT, nil
+	}
+	switch name := algo.ToString(); name {
+	case PASSWORD_BCRYPT, PASSWORD_ARGON2I, PASSWORD_ARGON2ID:
+		return name, nil
+	}
+	return "", valueError("%s(): Argument #2 ($algo) must be a valid password hashing algorithm", fn)
+}
+
+// passwordOption returns an integer option of the $options array
+func passwordOption(options *types.Value, name string, def int64) int64 {
+	if options == nil || !options.IsArray() {
+		return def
+	}
+	if value, ok := options.ToArray().Get(types.NewString(name)); ok {
+		return value.Deref().ToInt()
+	}
+	return def
+}
+
+// passwordOptions returns the wanted parameters of algo, validated
+func passwordOptions(algo string, options *types.Value) (passwordHash, error) {
+	p := passwordHash{algo: algo}
+	if algo == PASSWORD_BCRYPT {
+		cost := passwordOption(options, "cost", PASSWORD_BCRYPT_DEFAULT_COST)
+		if cost < int64(bcrypt.MinCost) || cost > int64(bcrypt.MaxCost) {
+			return p, valueError("Invalid bcrypt cost parameter specified: %d", cost)
+		}
+		p.cost = int(cost)
+		return p, nil
+	}
+
+	memory := passwordOption(options, "memory_cost", PASSWORD_ARGON2_DEFAULT_MEMORY_COST)
+	if memory < 8 || memory > 1<<32-1 {
+		return p, valueError("Memory cost is outside of allowed memory range")
+	}
+	time := passwordOption(options, "time_cost", PASSWORD_ARGON2_DEFAULT_TIME_COST)
+	if time < 1 || time > 1<<32-1 {
+		return p, valueError("Time cost is outside of allowed time range")
+	}
+	threads := passwordOption(options, "threads", PASSWORD_ARGON2_DEFAULT_THREADS)
+	if threads < 1 || threads > 255 {
+		return p, valueError("Invalid number of threads")
+	}
+	p.memory, p.time, p.threads = uint32(memory), uint32(time), uint8(threads)
+	return p, nil
+}
+
+// PasswordHash creates a password hash with a random salt
+// password_hash(string $password, string|int|null $algo, array $options = []): string
+func PasswordHash(password, algo *types.Value, options ...*types.Value) (*types.Value, error) {
+	name, err := passwordAlgo("password_hash", algo)
+	if err != nil {
+		return nil, err
+	}
+	var opts *types.Value
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	p, err := passwordOptions(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	pw := password.ToString()
+
+	if name == PASSWORD_BCRYPT {
+		if strings.IndexByte(pw, 0) >= 0 {
+			return nil, valueError("Bcrypt password must not contain null character")
+		}
+		// bcrypt only uses the first 72 bytes, which PHP silently truncates
+		if len(pw) > 72 {
+			pw = pw[:72]
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(pw), p.cost)
+		if err != nil {
+			return nil, &Error{Class: "Error", Message: "password_hash(): " + err.Error()}
+		}
+		// The hashes are identical; PHP marks them with its own $2y$ prefix
+		return types.NewString("$2y$" + string(hash[4:])), nil
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, &Error{Class: "Error", Message: "password_hash(): Failed to generate salt"}
+	}
+	key := argon2Key(name, pw, salt, p.time, p.memory, p.threads, argon2KeyLength)
+	return types.NewString(fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", name, argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))), nil
+}
+
+// PasswordVerify checks that a password matches a bcrypt or argon2 hash
+// password_verify(string $password, string $hash): bool
+func PasswordVerify(password, hash *types.Value) *types.Value {
+	pw, h := password.ToString(), hash.ToString()
+	p := parsePasswordHash(h)
+	switch p.algo {
+	case PASSWORD_BCRYPT:
+		if len(pw) > 72 {
+			pw = pw[:72]
+		}
+		return types.NewBool(bcrypt.CompareHashAndPassword([]byte(h), []byte(pw)) == nil)
+	case PASSWORD_ARGON2I, PASSWORD_ARGON2ID:
+		key := argon2Key(p.algo, pw, p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+		return types.NewBool(subtle.ConstantTimeCompare(key, p.key) == 1)
+	}
+	return types.NewBool(false)
+}
+
+// PasswordGetInfo returns the algorithm and options of a hash
+// password_get_info(string $hash): array
+func PasswordGetInfo(hash *types.Value) *types.Value {
+	p := parsePasswordHash(hash.ToString())
+	info := types.NewEmptyArray()
+	options := types.NewEmptyArray()
+	switch p.algo {
+	case PASSWORD_BCRYPT:
+		info.Set(types.NewString("algo"), types.NewString(p.algo))
+		info.Set(types.NewString("algoName"), types.NewString("bcrypt"))
+		options.Set(types.NewString("cost"), types.NewInt(int64(p.cost)))
+	case PASSWORD_ARGON2I, PASSWORD_ARGON2ID:
+		info.Set(types.NewString("algo"), types.NewString(p.algo))
+		info.Set(types.NewString("algoName"), types.NewString(p.algo))
+		options.Set(types.NewString("memory_cost"), types.NewInt(int64(p.memory)))
+		options.Set(types.NewString("time_cost"), types.NewInt(int64(p.time)))
+		options.Set(types.NewString("threads"), types.NewInt(int64(p.threads)))
+	default:
+		info.Set(types.NewString("algo"), types.NewNull())
+		info.Set(types.NewString("algoName"), types.NewString("unknown"))
+	}
+	info.Set(types.NewString("options"), types.NewArray(options))
+	return types.NewArray(info)
+}
+
+// PasswordNeedsRehash checks whether a hash was made with another
+// algorithm or other options than the given ones
+// password_needs_rehash(string $hash, string|int|null $algo, array $options = []): bool
+func PasswordNeedsRehash(hash, algo *types.Value, options ...*types.Value) (*types.Value, error) {
+	name, err := passwordAlgo("password_needs_rehash", algo)
+	if err != nil {
+		return nil, err
+	}
+	var opts *types.Value
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	want, err := passwordOptions(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	p := parsePasswordHash(hash.ToString())
+	if p.algo != name {
+		return types.NewBool(true), nil
+	}
+	return types.NewBool(p.cost != want.cost || p.memory != want.memory || p.time != want.time || p.threads != want.threads), nil
+}
+
+// PasswordAlgos returns the available password hashing algorithms
+// password_algos(): array
+func PasswordAlgos() *types.Value {
+	arr := types.NewEmptyArray()
+	for _, algo := range []string{PASSWORD_BCRYPT, PASSWORD_ARGON2I, PASSWORD_ARGON2ID} {
+		arr.Append(types.NewString(algo))
+	}
+	return types.NewArray(arr)
+}
+
+// Constants returns the constants defined by the hash extension and the
+// password API
+func Constants() map[string]*types.Value {
+	return map[string]*types.Value{
+		"HASH_HMAC": types.NewInt(HASH_HMAC),
+
+		"PASSWORD_DEFAULT":                    types.NewString(PASSWORD_DEFAULT),
+		"PASSWORD_BCRYPT":                     types.NewString(PASSWORD_BCRYPT),
+		"PASSWORD_ARGON2I":                    types.NewString(PASSWORD_ARGON2I),
+		"PASSWORD_ARGON2ID":                   types.NewString(PASSWORD_ARGON2ID),
+		"PASSWORD_BCRYPT_DEFAULT_COST":        types.NewInt(PASSWORD_BCRYPT_DEFAULT_COST),
+		"PASSWORD_ARGON2_DEFAULT_MEMORY_COST": types.NewInt(PASSWORD_ARGON2_DEFAULT_MEMORY_COST),
+		"PASSWORD_ARGON2_DEFAULT_TIME_COST":   types.NewInt(PASSWORD_ARGON2_DEFAULT_TIME_COST),
+		"PASSWORD_ARGON2_DEFAULT_THREADS":     types.NewInt(PASSWORD_ARGON2_DEFAULT_THREADS),
+		"PASSWORD_ARGON2_PROVIDER":            types.NewString("standard"),
+	}
+}
@@ -0,0 +1,84 @@
+package hash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestPasswordBcrypt(t *testing.T) {
+	options := types.NewEmptyArray()
+	options.Set(types.NewString("cost"), types.NewInt(4))
+	hash, err := PasswordHash(types.NewString("rasmuslerdorf"), types.NewString(PASSWORD_BCRYPT), types.NewArray(options))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := hash.ToString(); !strings.HasPrefix(h, "$2y$04$") || len(h) != 60 {
+		t.Fatalf("PasswordHash() = %s, want a $2y$04$ hash", h)
+	}
+
+	if !PasswordVerify(types.NewString("rasmuslerdorf"), hash).ToBool() {
+		t.Errorf("PasswordVerify() of the right password should succeed")
+	}
+	if PasswordVerify(types.NewString("wrong"), hash).ToBool() {
+		t.Errorf("PasswordVerify() of a wrong password should fail")
+	}
+
+	// A hash from the PHP manual
+	known := types.NewString("$2y$10$.vGA1O9wmRjrwAVXD98HNOgsNpDczlqm3Jq7KnEd1rVAGv3Fykk1a")
+	if !PasswordVerify(types.NewString("rasmuslerdorf"), known).ToBool() {
+		t.Errorf("PasswordVerify() of PHP's hash should succeed")
+	}
+
+	info := PasswordGetInfo(known).ToArray()
+	if algo, _ := info.Get(types.NewString("algoName")); algo.ToString() != "bcrypt" {
+		t.Errorf("PasswordGetInfo() algoName = %v", algo)
+	}
+	if rehash, _ := PasswordNeedsRehash(known, types.NewString(PASSWORD_BCRYPT)); !rehash.ToBool() {
+		t.Errorf("PasswordNeedsRehash() should be true for cost 10 against the default")
+	}
+}
+
+func TestPasswordArgon2(t *testing.T) {
+	options := types.NewEmptyArray()
+	options.Set(types.NewString("memory_cost"), types.NewInt(1024))
+	options.Set(types.NewString("time_cost"), types.NewInt(2))
+	for _, algo := range []string{PASSWORD_ARGON2I, PASSWORD_ARGON2ID} {
+		hash, err := PasswordHash(types.NewString("secret"), types.NewString(algo), types.NewArray(options))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(hash.ToString(), "$"+algo+"$v=19$m=1024,t=2,p=1$") {
+			t.Fatalf("PasswordHash(%s) = %s", algo, hash.ToString())
+		}
+		if !PasswordVerify(types.NewString("secret"), hash).ToBool() {
+			t.Errorf("PasswordVerify(%s) of the right password should succeed", algo)
+		}
+		if PasswordVerify(types.NewString("secreT"), hash).ToBool() {
+			t.Errorf("PasswordVerify(%s) of a wrong password should fail", algo)
+		}
+		if rehash, _ := PasswordNeedsRehash(hash, types.NewString(algo), types.NewArray(options)); rehash.ToBool() {
+			t.Errorf("PasswordNeedsRehash(%s) with the same options should be false", algo)
+		}
+	}
+}
+
+func TestPasswordErrors(t *testing.T) {
+	options := types.NewEmptyArray()
+	options.Set(types.NewString("cost"), types.NewInt(3))
+	_, err := PasswordHash(types.NewString("pw"), types.NewString(PASSWORD_BCRYPT), types.NewArray(options))
+	if e, ok := err.(*Error); !ok || e.Message != "Invalid bcrypt cost parameter specified: 3" {
+		t.Errorf("PasswordHash() with cost 3 error = %v", err)
+	}
+
+	_, err = PasswordHash(types.NewString("pw"), types.NewString("md5"))
+	if e, ok := err.(*Error); !ok || e.Class != "ValueError" {
+		t.Errorf("PasswordHash() with an unknown algorithm error = %v", err)
+	}
+
+	info := PasswordGetInfo(types.NewString("plain")).ToArray()
+	if algo, _ := info.Get(types.NewString("algo")); !algo.IsNull() {
+		t.Errorf("PasswordGetInfo() of an unknown hash algo = %v, want null", algo)
+	}
+}
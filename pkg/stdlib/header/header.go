@@ -0,0 +1,254 @@
+// Package header implements the HTTP response of a PHP request as the
+// SAPI layer sends it: the header lines and status code that header(),
+// header_remove() and http_response_code() change, and the Set-Cookie
+// lines of setcookie() and setrawcookie().
+package header
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error is a failure thrown as a PHP exception of Class
+type Error struct {
+	Class   string
+	Message string
+}
+
+// Error returns the message
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// ============================================================================
+// Response Headers
+// ============================================================================
+
+// Response holds the headers and status code of a response until the
+// SAPI layer sends them
+type Response struct {
+	Code  int // Status code, 0 until one is set
+	lines []string
+}
+
+// Lines returns the header lines ("Name: value") in the order they were
+// set
+func (r *Response) Lines() []string {
+	return r.lines
+}
+
+// Add sets a header line as header() does. A status line such as
+// "HTTP/1.1 404 Not Found" or a Status header sets the status code; a
+// Location header makes the response a 302 redirect unless the status is
+// already a redirect or 201. replace drops the earlier headers of the
+// same name. code, if not zero, sets the status code.
+func (r *Response) Add(line string, replace bool, code int) {
+	if strings.HasPrefix(strings.ToUpper(line), "HTTP/") {
+		if fields := strings.Fields(line); len(fields) > 1 {
+			if status, err := strconv.Atoi(fields[1]); err == nil {
+				r.Code = status
+			}
+		}
+		if code != 0 {
+			r.Code = code
+		}
+		return
+	}
+
+	name, value, ok := strings.Cut(line, ":")
+	if !ok {
+		// PHP ignores a header without a colon
+		return
+	}
+	name = strings.TrimSpace(name)
+	value = strings.TrimSpace(value)
+
+	switch {
+	case strings.EqualFold(name, "Status"):
+		if fields := strings.Fields(value); len(fields) > 0 {
+			if status, err := strconv.Atoi(fields[0]); err == nil {
+				r.Code = status
+			}
+		}
+		if code != 0 {
+			r.Code = code
+		}
+		return
+	case strings.EqualFold(name, "Location") && code == 0:
+		if r.Code != 201 && (r.Code < 300 || r.Code > 399) {
+			r.Code = 302
+		}
+	}
+	if code != 0 {
+		r.Code = code
+	}
+
+	if replace {
+		r.Remove(name)
+	}
+	r.lines = append(r.lines, name+": "+value)
+}
+
+// Remove drops the headers of a name, as header_remove() does; an empty
+// name drops them all
+func (r *Response) Remove(name string) {
+	if name == "" {
+		r.lines = nil
+		return
+	}
+	kept := r.lines[:0]
+	for _, line := range r.lines {
+		lineName, _, _ := strings.Cut(line, ":")
+		if !strings.EqualFold(lineName, name) {
+			kept = append(kept, line)
+		}
+	}
+	r.lines = kept
+}
+
+// Check returns the warning header() gives for a line it refuses: one of
+// several lines or with a NUL byte. It returns "" for a valid line.
+func Check(line string) string {
+	if strings.ContainsAny(line, "\r\n") {
+		return "Header may not contain more than a single header, new line detected"
+	}
+	if strings.IndexByte(line, 0) >= 0 {
+		return "Header may not contain NUL bytes"
+	}
+	return ""
+}
+
+// ============================================================================
+// Cookies
+// ============================================================================
+
+// Cookie is a cookie setcookie() sends
+type Cookie struct {
+	Name     string
+	Value    string
+	Expires  int64 // Unix time the cookie expires, 0 when the browser closes
+	Path     string
+	Domain   string
+	Secure   bool
+	HTTPOnly bool
+	SameSite string
+	Raw      bool // setrawcookie(): the value is sent as is
+}
+
+// cookieNameChars and cookieValueChars are the characters a cookie name,
+// or a raw value, path or domain, cannot contain
+const (
+	cookieNameChars  = "=,; \t\r\n\013\014"
+	cookieValueChars = ",; \t\r\n\013\014"
+)
+
+const (
+	cookieNameList  = `"=", ",", ";", " ", "\t", "\r", "\n", "\013", or "\014"`
+	cookieValueList = `",", ";", " ", "\t", "\r", "\n", "\013", or "\014"`
+)
+
+// cookieDate is the format of the expires attribute
+const cookieDate = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// Validate checks the cookie as setcookie() does before sending it,
+// naming the arguments of function; options tells whether the attributes
+// came in an options array
+func (c *Cookie) Validate(function string, options bool) error {
+	valueError := func(format string, args ...interface{}) error {
+		return &Error{Class: "ValueError", Message: function + "(): " + fmt.Sprintf(format, args...)}
+	}
+	if c.Name == "" {
+		return valueError("Argument #1 ($name) cannot be empty")
+	}
+	if strings.ContainsAny(c.Name, cookieNameChars) {
+		return valueError("Argument #1 ($name) cannot contain %s", cookieNameList)
+	}
+	if c.Raw && strings.ContainsAny(c.Value, cookieValueChars) {
+		return valueError("Argument #2 ($value) cannot contain %s", cookieValueList)
+	}
+	attributes := []struct {
+		value, option, argument string
+	}{
+		{c.Path, "path", "Argument #4 ($path)"},
+		{c.Domain, "domain", "Argument #5 ($domain)"},
+	}
+	for _, attribute := range attributes {
+		if !strings.ContainsAny(attribute.value, cookieValueChars) {
+			continue
+		}
+		if options {
+			return valueError("%q option cannot contain %s", attribute.option, cookieValueList)
+		}
+		return valueError("%s cannot contain %s", attribute.argument, cookieValueList)
+	}
+	if c.Expires > 253402300799 {
+		if options {
+			return valueError(`"expires" option cannot have a year greater than 9999`)
+		}
+		return valueError("Argument #3 ($expires_or_options) cannot have a year greater than 9999")
+	}
+	return nil
+}
+
+// Line returns the Set-Cookie header line of the cookie at a time. An
+// empty value deletes the cookie with an expiry date in the past.
+func (c *Cookie) Line(now time.Time) string {
+	var line strings.Builder
+	line.WriteString("Set-Cookie: ")
+	line.WriteString(c.Name)
+	line.WriteByte('=')
+
+	if c.Value == "" {
+		line.WriteString("deleted; expires=")
+		line.WriteString(time.Unix(1, 0).UTC().Format(cookieDate))
+		line.WriteString("; Max-Age=0")
+	} else {
+		if c.Raw {
+			line.WriteString(c.Value)
+		} else {
+			line.WriteString(rawURLEncode(c.Value))
+		}
+		if c.Expires > 0 {
+			line.WriteString("; expires=")
+			line.WriteString(time.Unix(c.Expires, 0).UTC().Format(cookieDate))
+			line.WriteString("; Max-Age=")
+			line.WriteString(strconv.FormatInt(max(c.Expires-now.Unix(), 0), 10))
+		}
+	}
+
+	if c.Path != "" {
+		line.WriteString("; path=" + c.Path)
+	}
+	if c.Domain != "" {
+		line.WriteString("; domain=" + c.Domain)
+	}
+	if c.Secure {
+		line.WriteString("; secure")
+	}
+	if c.HTTPOnly {
+		line.WriteString("; HttpOnly")
+	}
+	if c.SameSite != "" {
+		line.WriteString("; SameSite=" + c.SameSite)
+	}
+	return line.String()
+}
+
+// rawURLEncode encodes a cookie value as rawurlencode() does
+func rawURLEncode(s string) string {
+	const hex = "0123456789ABCDEF"
+	var encoded strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' || strings.IndexByte("-_.~", ch) >= 0 {
+			encoded.WriteByte(ch)
+		} else {
+			encoded.WriteByte('%')
+			encoded.WriteByte(hex[ch>>4])
+			encoded.WriteByte(hex[ch&0xF])
+		}
+	}
+	return encoded.String()
+}
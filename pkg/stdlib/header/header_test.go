@@ -0,0 +1,119 @@
+package header
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResponse_Add(t *testing.T) {
+	var r Response
+	r.Add("Content-Type: text/plain", true, 0)
+	r.Add("X-Tag: a", true, 0)
+	r.Add("X-Tag: b", false, 0)
+	r.Add("content-type:application/json", true, 0)
+	r.Add("no colon", true, 0)
+
+	want := []string{"X-Tag: a", "X-Tag: b", "content-type: application/json"}
+	if !reflect.DeepEqual(r.Lines(), want) {
+		t.Errorf("Expected %q, got %q", want, r.Lines())
+	}
+
+	r.Remove("x-tag")
+	if want := []string{"content-type: application/json"}; !reflect.DeepEqual(r.Lines(), want) {
+		t.Errorf("After Remove: expected %q, got %q", want, r.Lines())
+	}
+	r.Remove("")
+	if len(r.Lines()) != 0 {
+		t.Errorf("Remove(\"\") should drop every header, got %q", r.Lines())
+	}
+}
+
+func TestResponse_StatusCode(t *testing.T) {
+	tests := []struct {
+		name  string
+		start int
+		line  string
+		code  int
+		want  int
+	}{
+		{"status line", 200, "HTTP/1.1 404 Not Found", 0, 404},
+		{"Status header", 200, "Status: 503 Service Unavailable", 0, 503},
+		{"explicit code", 200, "X-Test: 1", 418, 418},
+		{"redirect", 200, "Location: /home", 0, 302},
+		{"redirect keeps 301", 301, "Location: /home", 0, 301},
+		{"redirect keeps 201", 201, "Location: /item/1", 0, 201},
+		{"redirect with code", 200, "Location: /home", 307, 307},
+	}
+	for _, tt := range tests {
+		r := Response{Code: tt.start}
+		r.Add(tt.line, true, tt.code)
+		if r.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, r.Code)
+		}
+	}
+
+	r := Response{Code: 200}
+	r.Add("Status: 404", true, 0)
+	if len(r.Lines()) != 0 {
+		t.Errorf("A Status header should only set the code, got %q", r.Lines())
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if Check("X-Test: 1") != "" {
+		t.Error("A single header line should be accepted")
+	}
+	if Check("X-Test: 1\r\nX-Injected: 2") == "" {
+		t.Error("A header with a new line should be refused")
+	}
+	if Check("X-Test: \x00") == "" {
+		t.Error("A header with a NUL byte should be refused")
+	}
+}
+
+func TestCookie_Line(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		cookie Cookie
+		want   string
+	}{
+		{Cookie{Name: "a", Value: "b c/d"}, "Set-Cookie: a=b%20c%2Fd"},
+		{Cookie{Name: "a", Value: "b c", Raw: true}, "Set-Cookie: a=b c"},
+		{
+			Cookie{Name: "id", Value: "1", Expires: 1700003600, Path: "/", Domain: "example.com", Secure: true, HTTPOnly: true, SameSite: "Lax"},
+			"Set-Cookie: id=1; expires=Tue, 14 Nov 2023 23:13:20 GMT; Max-Age=3600; path=/; domain=example.com; secure; HttpOnly; SameSite=Lax",
+		},
+		{Cookie{Name: "gone"}, "Set-Cookie: gone=deleted; expires=Thu, 01 Jan 1970 00:00:01 GMT; Max-Age=0"},
+	}
+	for _, tt := range tests {
+		if got := tt.cookie.Line(now); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestCookie_Validate(t *testing.T) {
+	tests := []struct {
+		cookie  Cookie
+		options bool
+		want    string
+	}{
+		{Cookie{Name: "ok", Value: "a b"}, false, ""},
+		{Cookie{}, false, "setcookie(): Argument #1 ($name) cannot be empty"},
+		{Cookie{Name: "a=b"}, false, `setcookie(): Argument #1 ($name) cannot contain "=", ",", ";", " ", "\t", "\r", "\n", "\013", or "\014"`},
+		{Cookie{Name: "a", Value: "b c", Raw: true}, false, `setcookie(): Argument #2 ($value) cannot contain ",", ";", " ", "\t", "\r", "\n", "\013", or "\014"`},
+		{Cookie{Name: "a", Path: "/a;b"}, true, `setcookie(): "path" option cannot contain ",", ";", " ", "\t", "\r", "\n", "\013", or "\014"`},
+		{Cookie{Name: "a", Domain: "a b"}, false, `setcookie(): Argument #5 ($domain) cannot contain ",", ";", " ", "\t", "\r", "\n", "\013", or "\014"`},
+	}
+	for _, tt := range tests {
+		err := tt.cookie.Validate("setcookie", tt.options)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package json
 
 import (
-	"encoding/json"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	"github.com/krizos/php-go/pkg/types"
 )
@@ -14,21 +17,23 @@ import (
 // ============================================================================
 
 const (
-	JSON_HEX_TAG             = 1 << 0  // 1
-	JSON_HEX_AMP             = 1 << 1  // 2
-	JSON_HEX_APOS            = 1 << 2  // 4
-	JSON_HEX_QUOT            = 1 << 3  // 8
-	JSON_FORCE_OBJECT        = 1 << 4  // 16
-	JSON_NUMERIC_CHECK       = 1 << 5  // 32
-	JSON_UNESCAPED_SLASHES   = 1 << 6  // 64
-	JSON_PRETTY_PRINT        = 1 << 7  // 128
-	JSON_UNESCAPED_UNICODE   = 1 << 8  // 256
-	JSON_PARTIAL_OUTPUT_ON_ERROR = 1 << 9  // 512
-	JSON_PRESERVE_ZERO_FRACTION = 1 << 10 // 1024
+	JSON_HEX_TAG                    = 1 << 0  // 1
+	JSON_HEX_AMP                    = 1 << 1  // 2
+	JSON_HEX_APOS                   = 1 << 2  // 4
+	JSON_HEX_QUOT                   = 1 << 3  // 8
+	JSON_FORCE_OBJECT               = 1 << 4  // 16
+	JSON_NUMERIC_CHECK              = 1 << 5  // 32
+	JSON_UNESCAPED_SLASHES          = 1 << 6  // 64
+	JSON_PRETTY_PRINT               = 1 << 7  // 128
+	JSON_UNESCAPED_UNICODE          = 1 << 8  // 256
+	JSON_PARTIAL_OUTPUT_ON_ERROR    = 1 << 9  // 512
+	JSON_PRESERVE_ZERO_FRACTION     = 1 << 10 // 1024
 	JSON_UNESCAPED_LINE_TERMINATORS = 1 << 11 // 2048
-	JSON_OBJECT_AS_ARRAY     = 1 << 0  // For decode
-	JSON_BIGINT_AS_STRING    = 1 << 1  // For decode
-	JSON_THROW_ON_ERROR      = 1 << 22 // 4194304
+	JSON_OBJECT_AS_ARRAY            = 1 << 0  // For decode
+	JSON_BIGINT_AS_STRING           = 1 << 1  // For decode
+	JSON_INVALID_UTF8_IGNORE        = 1 << 20 // 1048576
+	JSON_INVALID_UTF8_SUBSTITUTE    = 1 << 21 // 2097152
+	JSON_THROW_ON_ERROR             = 1 << 22 // 4194304
 )
 
 // JSON error constants
@@ -42,93 +47,201 @@ const (
 	JSON_ERROR_RECURSION
 	JSON_ERROR_INF_OR_NAN
 	JSON_ERROR_UNSUPPORTED_TYPE
+	JSON_ERROR_INVALID_PROPERTY_NAME
+	JSON_ERROR_UTF16
 )
 
-var lastJsonError = JSON_ERROR_NONE
+// Constants returns the constants defined by the JSON extension
+func Constants() map[string]*types.Value {
+	constants := make(map[string]*types.Value)
+	for name, value := range map[string]int64{
+		"JSON_HEX_TAG":                    JSON_HEX_TAG,
+		"JSON_HEX_AMP":                    JSON_HEX_AMP,
+		"JSON_HEX_APOS":                   JSON_HEX_APOS,
+		"JSON_HEX_QUOT":                   JSON_HEX_QUOT,
+		"JSON_FORCE_OBJECT":               JSON_FORCE_OBJECT,
+		"JSON_NUMERIC_CHECK":              JSON_NUMERIC_CHECK,
+		"JSON_UNESCAPED_SLASHES":          JSON_UNESCAPED_SLASHES,
+		"JSON_PRETTY_PRINT":               JSON_PRETTY_PRINT,
+		"JSON_UNESCAPED_UNICODE":          JSON_UNESCAPED_UNICODE,
+		"JSON_PARTIAL_OUTPUT_ON_ERROR":    JSON_PARTIAL_OUTPUT_ON_ERROR,
+		"JSON_PRESERVE_ZERO_FRACTION":     JSON_PRESERVE_ZERO_FRACTION,
+		"JSON_UNESCAPED_LINE_TERMINATORS": JSON_UNESCAPED_LINE_TERMINATORS,
+		"JSON_OBJECT_AS_ARRAY":            JSON_OBJECT_AS_ARRAY,
+		"JSON_BIGINT_AS_STRING":           JSON_BIGINT_AS_STRING,
+		"JSON_INVALID_UTF8_IGNORE":        JSON_INVALID_UTF8_IGNORE,
+		"JSON_INVALID_UTF8_SUBSTITUTE":    JSON_INVALID_UTF8_SUBSTITUTE,
+		"JSON_THROW_ON_ERROR":             JSON_THROW_ON_ERROR,
+
+		"JSON_ERROR_NONE":                  JSON_ERROR_NONE,
+		"JSON_ERROR_DEPTH":                 JSON_ERROR_DEPTH,
+		"JSON_ERROR_STATE_MISMATCH":        JSON_ERROR_STATE_MISMATCH,
+		"JSON_ERROR_CTRL_CHAR":             JSON_ERROR_CTRL_CHAR,
+		"JSON_ERROR_SYNTAX":                JSON_ERROR_SYNTAX,
+		"JSON_ERROR_UTF8":                  JSON_ERROR_UTF8,
+		"JSON_ERROR_RECURSION":             JSON_ERROR_RECURSION,
+		"JSON_ERROR_INF_OR_NAN":            JSON_ERROR_INF_OR_NAN,
+		"JSON_ERROR_UNSUPPORTED_TYPE":      JSON_ERROR_UNSUPPORTED_TYPE,
+		"JSON_ERROR_INVALID_PROPERTY_NAME": JSON_ERROR_INVALID_PROPERTY_NAME,
+		"JSON_ERROR_UTF16":                 JSON_ERROR_UTF16,
+	} {
+		constants[name] = types.NewInt(value)
+	}
+	return constants
+}
+
+// DefaultDepth is the default nesting limit of json_encode and json_decode
+const DefaultDepth = 512
+
+// lastJsonError is the error of the last json_encode/json_decode call
+// without JSON_THROW_ON_ERROR (atomic, since parallel tasks share it)
+var lastJsonError atomic.Int64
+
+// Error is a JSON encoding or decoding error; Code is a JSON_ERROR_* constant
+type Error struct {
+	Code int
+}
+
+func (e *Error) Error() string {
+	return errorMessage(e.Code)
+}
+
+// errorCode returns the JSON_ERROR_* code of an error
+func errorCode(err error) int {
+	if e, ok := err.(*Error); ok {
+		return e.Code
+	}
+	return JSON_ERROR_SYNTAX
+}
 
 // ============================================================================
 // JSON Encode
 // ============================================================================
 
-// JsonEncode returns the JSON representation of a value
-// json_encode(mixed $value, int $flags = 0, int $depth = 512): string|false
-func JsonEncode(value *types.Value, flags ...*types.Value) *types.Value {
-	options := 0
-	depth := 512
+// Serializer returns the value to encode in place of an object, for objects
+// that serialize themselves (JsonSerializable). It returns false to encode
+// the object's public properties.
+type Serializer func(obj *types.Object) (*types.Value, bool, error)
 
-	if len(flags) > 0 && flags[0] != nil {
-		options = int(flags[0].ToInt())
-	}
+// Encoder encodes PHP values as JSON
+type Encoder struct {
+	Options   int        // JSON_* encode flags
+	Depth     int        // Maximum nesting depth of arrays and objects
+	Serialize Serializer // Custom object serialization (nil encodes public properties)
 
-	if len(flags) > 1 && flags[1] != nil {
-		depth = int(flags[1].ToInt())
-	}
+	err     error           // First error, kept for JSON_PARTIAL_OUTPUT_ON_ERROR
+	visited map[uint64]bool // Objects being encoded, to detect recursion
+}
 
-	// Convert PHP value to Go interface for JSON encoding
-	result, err := encodeValue(value, options, depth, 0)
-	if err != nil {
-		lastJsonError = JSON_ERROR_SYNTAX
-		return types.NewBool(false)
-	}
+// NewEncoder creates an encoder with the given flags and depth
+func NewEncoder(options, depth int, serialize Serializer) *Encoder {
+	return &Encoder{Options: options, Depth: depth, Serialize: serialize}
+}
 
-	lastJsonError = JSON_ERROR_NONE
-	return types.NewString(result)
+// Encode returns the JSON representation of a value. With
+// JSON_PARTIAL_OUTPUT_ON_ERROR, values that cannot be encoded are replaced
+// and the output is returned together with the first error.
+func (e *Encoder) Encode(value *types.Value) (string, error) {
+	e.err = nil
+	e.visited = make(map[uint64]bool)
+
+	var out strings.Builder
+	if err := e.encodeValue(&out, value, 0); err != nil {
+		return "", err
+	}
+	return out.String(), e.err
 }
 
-// encodeValue recursively encodes a PHP value to JSON
-func encodeValue(value *types.Value, options int, maxDepth int, currentDepth int) (string, error) {
-	if currentDepth > maxDepth {
-		return "", fmt.Errorf("maximum depth exceeded")
+// fail records an encoding error. With JSON_PARTIAL_OUTPUT_ON_ERROR the
+// error is kept and the substitute is written instead of failing.
+func (e *Encoder) fail(out *strings.Builder, code int, substitute string) error {
+	if e.Options&JSON_PARTIAL_OUTPUT_ON_ERROR == 0 {
+		return &Error{Code: code}
+	}
+	if e.err == nil {
+		e.err = &Error{Code: code}
 	}
+	out.WriteString(substitute)
+	return nil
+}
+
+// encodeValue encodes a PHP value at the given nesting depth
+func (e *Encoder) encodeValue(out *strings.Builder, value *types.Value, depth int) error {
+	value = value.Deref()
 
 	switch value.Type() {
-	case types.TypeNull:
-		return "null", nil
+	case types.TypeNull, types.TypeUndef:
+		out.WriteString("null")
 
 	case types.TypeBool:
 		if value.ToBool() {
-			return "true", nil
+			out.WriteString("true")
+		} else {
+			out.WriteString("false")
 		}
-		return "false", nil
 
 	case types.TypeInt:
-		return strconv.FormatInt(value.ToInt(), 10), nil
+		out.WriteString(strconv.FormatInt(value.ToInt(), 10))
 
 	case types.TypeFloat:
-		f := value.ToFloat()
-		// Check for infinity or NaN
-		if !isFinite(f) {
-			if options&JSON_PARTIAL_OUTPUT_ON_ERROR != 0 {
-				return "0", nil
-			}
-			return "", fmt.Errorf("inf or nan")
-		}
+		return e.encodeFloat(out, value.ToFloat())
 
-		str := strconv.FormatFloat(f, 'f', -1, 64)
-		// JSON_PRESERVE_ZERO_FRACTION: ensure .0 for whole numbers
-		if options&JSON_PRESERVE_ZERO_FRACTION != 0 {
-			if !strings.Contains(str, ".") {
-				str += ".0"
+	case types.TypeString:
+		s := value.ToString()
+		if e.Options&JSON_NUMERIC_CHECK != 0 {
+			if n, ok := numericValue(s); ok {
+				return e.encodeValue(out, n, depth)
 			}
 		}
-		return str, nil
-
-	case types.TypeString:
-		return encodeString(value.ToString(), options), nil
+		return e.encodeString(out, s)
 
 	case types.TypeArray:
-		return encodeArray(value.ToArray(), options, maxDepth, currentDepth)
+		return e.encodeArray(out, value.ToArray(), depth)
 
 	case types.TypeObject:
-		return encodeObject(value.ToObject(), options, maxDepth, currentDepth)
+		return e.encodeObject(out, value.ToObject(), depth)
 
 	default:
-		return "", fmt.Errorf("unsupported type")
+		return e.fail(out, JSON_ERROR_UNSUPPORTED_TYPE, "null")
+	}
+	return nil
+}
+
+// encodeFloat encodes a float; INF and NAN cannot be represented
+func (e *Encoder) encodeFloat(out *strings.Builder, f float64) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return e.fail(out, JSON_ERROR_INF_OR_NAN, "0")
+	}
+
+	// serialize_precision digits, with a lowercase exponent (1.0e+25)
+	str := strings.Replace(types.FormatFloat(f, types.SerializePrecision()), "E", "e", 1)
+	// JSON_PRESERVE_ZERO_FRACTION: ensure .0 for whole numbers
+	if e.Options&JSON_PRESERVE_ZERO_FRACTION != 0 && !strings.ContainsAny(str, ".e") {
+		str += ".0"
+	}
+	out.WriteString(str)
+	return nil
+}
+
+// encodeString encodes a string with JSON escaping. Invalid UTF-8 is an
+// error unless JSON_INVALID_UTF8_IGNORE or _SUBSTITUTE is set.
+func (e *Encoder) encodeString(out *strings.Builder, s string) error {
+	if !utf8.ValidString(s) {
+		switch {
+		case e.Options&JSON_INVALID_UTF8_SUBSTITUTE != 0:
+			s = strings.ToValidUTF8(s, "\uFFFD")
+		case e.Options&JSON_INVALID_UTF8_IGNORE != 0:
+			s = strings.ToValidUTF8(s, "")
+		default:
+			return e.fail(out, JSON_ERROR_UTF8, "null")
+		}
 	}
+	out.WriteString(escapeString(s, e.Options))
+	return nil
 }
 
-// encodeString encodes a string with proper JSON escaping
-func encodeString(s string, options int) string {
+// escapeString quotes a valid UTF-8 string according to the encode flags
+func escapeString(s string, options int) string {
 	var result strings.Builder
 	result.WriteByte('"')
 
@@ -182,14 +295,28 @@ func encodeString(s string, options int) string {
 			} else {
 				result.WriteRune(r)
 			}
+		case '\u2028', '\u2029':
+			// Line terminators are valid JSON but not valid JavaScript
+			if options&JSON_UNESCAPED_UNICODE != 0 && options&JSON_UNESCAPED_LINE_TERMINATORS != 0 {
+				result.WriteRune(r)
+			} else {
+				fmt.Fprintf(&result, "\\u%04x", r)
+			}
 		default:
-			if r < 0x20 {
+			switch {
+			case r < 0x20:
 				// Control character
-				result.WriteString(fmt.Sprintf("\\u%04x", r))
-			} else if r > 0x7F && options&JSON_UNESCAPED_UNICODE == 0 {
-				// Non-ASCII - escape unless flag set
-				result.WriteString(fmt.Sprintf("\\u%04x", r))
-			} else {
+				fmt.Fprintf(&result, "\\u%04x", r)
+			case r > 0x7F && options&JSON_UNESCAPED_UNICODE == 0:
+				// Non-ASCII - escape unless flag set, as a surrogate pair
+				// outside the basic multilingual plane
+				if r > 0xFFFF {
+					hi, lo := utf16.EncodeRune(r)
+					fmt.Fprintf(&result, "\\u%04x\\u%04x", hi, lo)
+				} else {
+					fmt.Fprintf(&result, "\\u%04x", r)
+				}
+			default:
 				result.WriteRune(r)
 			}
 		}
@@ -199,19 +326,11 @@ func encodeString(s string, options int) string {
 	return result.String()
 }
 
-// encodeArray encodes a PHP array to JSON
-func encodeArray(arr *types.Array, options int, maxDepth int, currentDepth int) (string, error) {
-	if arr.IsEmpty() {
-		if options&JSON_FORCE_OBJECT != 0 {
-			return "{}", nil
-		}
-		return "[]", nil
-	}
-
-	// Determine if array is sequential (list) or associative (object)
+// isList reports whether an array has the keys 0, 1, 2, ... in order, and
+// is therefore encoded as a JSON array
+func isList(arr *types.Array) bool {
 	isSequential := true
 	expectedIndex := int64(0)
-
 	arr.Each(func(key, _ *types.Value) bool {
 		if key.Type() != types.TypeInt || key.ToInt() != expectedIndex {
 			isSequential = false
@@ -220,204 +339,566 @@ func encodeArray(arr *types.Array, options int, maxDepth int, currentDepth int)
 		expectedIndex++
 		return true
 	})
+	return isSequential
+}
+
+// member is a key and value of an encoded JSON object or array
+type member struct {
+	key   string
+	value *types.Value
+}
 
-	if options&JSON_FORCE_OBJECT != 0 {
-		isSequential = false
+// encodeArray encodes a PHP array as a JSON array or object
+func (e *Encoder) encodeArray(out *strings.Builder, arr *types.Array, depth int) error {
+	asObject := e.Options&JSON_FORCE_OBJECT != 0 || !isList(arr)
+
+	members := make([]member, 0, arr.Len())
+	arr.Each(func(key, value *types.Value) bool {
+		members = append(members, member{key: key.ToString(), value: value})
+		return true
+	})
+	return e.encodeMembers(out, members, asObject, depth)
+}
+
+// encodeObject encodes an object: the value returned by the Serializer, or
+// its public properties
+func (e *Encoder) encodeObject(out *strings.Builder, obj *types.Object, depth int) error {
+	if e.visited[obj.ObjectID] {
+		return e.fail(out, JSON_ERROR_RECURSION, "null")
 	}
+	e.visited[obj.ObjectID] = true
+	defer delete(e.visited, obj.ObjectID)
 
-	var result strings.Builder
-	first := true
+	if e.Serialize != nil {
+		value, ok, err := e.Serialize(obj)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return e.encodeValue(out, value, depth)
+		}
+	}
 
-	if isSequential {
-		result.WriteByte('[')
-		arr.Each(func(_, value *types.Value) bool {
-			if !first {
-				result.WriteByte(',')
-			}
-			first = false
+	var members []member
+	for _, prop := range obj.DebugProperties() {
+		if prop.Visibility == types.VisibilityPublic {
+			members = append(members, member{key: prop.Key.ToString(), value: prop.Value})
+		}
+	}
+	return e.encodeMembers(out, members, true, depth)
+}
 
-			encoded, err := encodeValue(value, options, maxDepth, currentDepth+1)
-			if err != nil {
-				return false
-			}
-			result.WriteString(encoded)
-			return true
-		})
-		result.WriteByte(']')
-	} else {
-		result.WriteByte('{')
-		arr.Each(func(key, value *types.Value) bool {
-			if !first {
-				result.WriteByte(',')
-			}
-			first = false
+// encodeMembers writes a JSON object or array, one member per line with
+// JSON_PRETTY_PRINT
+func (e *Encoder) encodeMembers(out *strings.Builder, members []member, asObject bool, depth int) error {
+	if depth >= e.Depth {
+		return e.fail(out, JSON_ERROR_DEPTH, "null")
+	}
 
-			// Encode key as string
-			keyStr := key.ToString()
-			result.WriteString(encodeString(keyStr, options))
-			result.WriteByte(':')
+	open, close := byte('['), byte(']')
+	if asObject {
+		open, close = '{', '}'
+	}
+	out.WriteByte(open)
+	if len(members) == 0 {
+		out.WriteByte(close)
+		return nil
+	}
 
-			encoded, err := encodeValue(value, options, maxDepth, currentDepth+1)
-			if err != nil {
-				return false
+	pretty := e.Options&JSON_PRETTY_PRINT != 0
+	for i, m := range members {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		if pretty {
+			out.WriteByte('\n')
+			out.WriteString(strings.Repeat("    ", depth+1))
+		}
+		if asObject {
+			out.WriteString(escapeString(strings.ToValidUTF8(m.key, "\uFFFD"), e.Options))
+			out.WriteByte(':')
+			if pretty {
+				out.WriteByte(' ')
 			}
-			result.WriteString(encoded)
-			return true
-		})
-		result.WriteByte('}')
+		}
+		if err := e.encodeValue(out, m.value, depth+1); err != nil {
+			return err
+		}
+	}
+	if pretty {
+		out.WriteByte('\n')
+		out.WriteString(strings.Repeat("    ", depth))
 	}
+	out.WriteByte(close)
+	return nil
+}
 
-	return result.String(), nil
+// numericValue converts a numeric string for JSON_NUMERIC_CHECK
+func numericValue(s string) (*types.Value, bool) {
+	trimmed := strings.TrimLeft(s, " \t\n\r\v\f")
+	if trimmed == "" {
+		return nil, false
+	}
+	if n, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+		return types.NewInt(n), true
+	}
+	if f, err := strconv.ParseFloat(trimmed, 64); err == nil && !strings.ContainsAny(trimmed, "xXnN_") {
+		return types.NewFloat(f), true
+	}
+	return nil, false
 }
 
-// encodeObject encodes a PHP object to JSON
-func encodeObject(obj *types.Object, options int, maxDepth int, currentDepth int) (string, error) {
-	var result strings.Builder
-	result.WriteByte('{')
+// JsonEncode returns the JSON representation of a value
+// json_encode(mixed $value, int $flags = 0, int $depth = 512): string|false
+func JsonEncode(value *types.Value, flags ...*types.Value) *types.Value {
+	options := 0
+	depth := DefaultDepth
 
-	first := true
-	for name, _ := range obj.Properties {
-		if !first {
-			result.WriteByte(',')
-		}
-		first = false
+	if len(flags) > 0 && flags[0] != nil {
+		options = int(flags[0].ToInt())
+	}
 
-		// Property name
-		result.WriteString(encodeString(name, options))
-		result.WriteByte(':')
+	if len(flags) > 1 && flags[1] != nil {
+		depth = int(flags[1].ToInt())
+	}
 
-		// Property value (pass nil for access context to get any property)
-		value, _ := obj.GetProperty(name, nil)
-		encoded, err := encodeValue(value, options, maxDepth, currentDepth+1)
+	result, err := NewEncoder(options, depth, nil).Encode(value)
+	return encodeResult(result, err, options)
+}
+
+// encodeResult records the outcome of an encode call and converts it to the
+// json_encode() return value
+func encodeResult(result string, err error, options int) *types.Value {
+	if options&JSON_THROW_ON_ERROR == 0 {
 		if err != nil {
-			return "", err
+			lastJsonError.Store(int64(errorCode(err)))
+		} else {
+			lastJsonError.Store(JSON_ERROR_NONE)
 		}
-		result.WriteString(encoded)
 	}
+	if err != nil && options&JSON_PARTIAL_OUTPUT_ON_ERROR == 0 {
+		return types.NewBool(false)
+	}
+	return types.NewString(result)
+}
 
-	result.WriteByte('}')
-	return result.String(), nil
+// Encode returns the JSON representation of a value for json_encode(),
+// using serialize for objects. Errors are recorded for json_last_error()
+// unless JSON_THROW_ON_ERROR is set, in which case they are returned.
+func Encode(value *types.Value, options, depth int, serialize Serializer) (*types.Value, error) {
+	result, err := NewEncoder(options, depth, serialize).Encode(value)
+	if _, ok := err.(*Error); err != nil && !ok {
+		// Serializer failure (an exception thrown by jsonSerialize())
+		return nil, err
+	}
+	if err != nil && options&JSON_THROW_ON_ERROR != 0 && options&JSON_PARTIAL_OUTPUT_ON_ERROR == 0 {
+		return nil, err
+	}
+	return encodeResult(result, err, options), nil
 }
 
 // ============================================================================
 // JSON Decode
 // ============================================================================
 
-// JsonDecode decodes a JSON string
-// json_decode(string $json, bool $associative = false, int $depth = 512, int $flags = 0): mixed
-func JsonDecode(jsonStr *types.Value, args ...*types.Value) *types.Value {
-	associative := false
-	depth := 512
-	flags := 0
+// Decoder parses JSON text into PHP values
+type Decoder struct {
+	Associative bool // Decode objects as associative arrays
+	Depth       int  // Maximum nesting depth of arrays and objects
+	Options     int  // JSON_* decode flags
 
-	if len(args) > 0 && args[0] != nil {
-		associative = args[0].ToBool()
+	data string
+	pos  int
+}
+
+// NewDecoder creates a decoder with the given options
+func NewDecoder(associative bool, depth, options int) *Decoder {
+	return &Decoder{Associative: associative || options&JSON_OBJECT_AS_ARRAY != 0, Depth: depth, Options: options}
+}
+
+// Decode parses a complete JSON text
+func (d *Decoder) Decode(data string) (*types.Value, error) {
+	d.data, d.pos = data, 0
+
+	d.skipWhitespace()
+	value, err := d.parseValue(0)
+	if err != nil {
+		return nil, err
+	}
+	d.skipWhitespace()
+	if d.pos < len(d.data) {
+		return nil, d.syntaxError()
 	}
+	return value, nil
+}
 
-	if len(args) > 1 && args[1] != nil {
-		depth = int(args[1].ToInt())
+// syntaxError reports a syntax error, or a control character error if the
+// parser stopped at one
+func (d *Decoder) syntaxError() error {
+	if d.pos < len(d.data) && d.data[d.pos] < 0x20 && !isWhitespace(d.data[d.pos]) {
+		return &Error{Code: JSON_ERROR_CTRL_CHAR}
 	}
+	return &Error{Code: JSON_ERROR_SYNTAX}
+}
 
-	if len(args) > 2 && args[2] != nil {
-		flags = int(args[2].ToInt())
+// isWhitespace reports whether c is JSON whitespace
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func (d *Decoder) skipWhitespace() {
+	for d.pos < len(d.data) && isWhitespace(d.data[d.pos]) {
+		d.pos++
+	}
+}
+
+// parseValue parses a value at the current position; depth is the number
+// of enclosing arrays and objects
+func (d *Decoder) parseValue(depth int) (*types.Value, error) {
+	if d.pos >= len(d.data) {
+		return nil, d.syntaxError()
 	}
 
-	str := jsonStr.ToString()
+	switch c := d.data[d.pos]; {
+	case c == '{':
+		return d.parseObject(depth + 1)
+	case c == '[':
+		return d.parseArray(depth + 1)
+	case c == '"':
+		s, err := d.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return types.NewString(s), nil
+	case c == '-' || c >= '0' && c <= '9':
+		return d.parseNumber()
+	case strings.HasPrefix(d.data[d.pos:], "true"):
+		d.pos += 4
+		return types.NewBool(true), nil
+	case strings.HasPrefix(d.data[d.pos:], "false"):
+		d.pos += 5
+		return types.NewBool(false), nil
+	case strings.HasPrefix(d.data[d.pos:], "null"):
+		d.pos += 4
+		return types.NewNull(), nil
+	default:
+		return nil, d.syntaxError()
+	}
+}
 
-	// Use Go's JSON decoder
-	var result interface{}
-	decoder := json.NewDecoder(strings.NewReader(str))
+// parseArray parses a JSON array into a list
+func (d *Decoder) parseArray(depth int) (*types.Value, error) {
+	if depth > d.Depth {
+		return nil, &Error{Code: JSON_ERROR_DEPTH}
+	}
+	d.pos++ // [
 
-	if err := decoder.Decode(&result); err != nil {
-		lastJsonError = JSON_ERROR_SYNTAX
-		return types.NewNull()
+	arr := types.NewEmptyArray()
+	d.skipWhitespace()
+	if d.pos < len(d.data) && d.data[d.pos] == ']' {
+		d.pos++
+		return types.NewArray(arr), nil
 	}
 
-	lastJsonError = JSON_ERROR_NONE
-	return convertFromJSON(result, associative, flags, 0, depth)
+	for {
+		d.skipWhitespace()
+		value, err := d.parseValue(depth)
+		if err != nil {
+			return nil, err
+		}
+		arr.Append(value)
+
+		d.skipWhitespace()
+		if d.pos >= len(d.data) {
+			return nil, d.syntaxError()
+		}
+		switch d.data[d.pos] {
+		case ',':
+			d.pos++
+		case ']':
+			d.pos++
+			return types.NewArray(arr), nil
+		default:
+			return nil, d.syntaxError()
+		}
+	}
 }
 
-// convertFromJSON converts Go JSON types to PHP types
-func convertFromJSON(val interface{}, associative bool, flags int, currentDepth int, maxDepth int) *types.Value {
-	if currentDepth > maxDepth {
-		lastJsonError = JSON_ERROR_DEPTH
-		return types.NewNull()
+// parseObject parses a JSON object into an associative array or a stdClass
+// object, keeping the key order
+func (d *Decoder) parseObject(depth int) (*types.Value, error) {
+	if depth > d.Depth {
+		return nil, &Error{Code: JSON_ERROR_DEPTH}
+	}
+	d.pos++ // {
+
+	var arr *types.Array
+	var obj *types.Object
+	if d.Associative {
+		arr = types.NewEmptyArray()
+	} else {
+		obj = types.NewObjectInstance("stdClass")
+	}
+	result := func() *types.Value {
+		if arr != nil {
+			return types.NewArray(arr)
+		}
+		return types.NewObject(obj)
 	}
 
-	switch v := val.(type) {
-	case nil:
-		return types.NewNull()
+	d.skipWhitespace()
+	if d.pos < len(d.data) && d.data[d.pos] == '}' {
+		d.pos++
+		return result(), nil
+	}
 
-	case bool:
-		return types.NewBool(v)
+	for {
+		d.skipWhitespace()
+		if d.pos >= len(d.data) || d.data[d.pos] != '"' {
+			return nil, d.syntaxError()
+		}
+		key, err := d.parseString()
+		if err != nil {
+			return nil, err
+		}
 
-	case float64:
-		// JSON numbers are always floats in Go
-		// Check if it's actually an integer
-		if v == float64(int64(v)) && flags&JSON_BIGINT_AS_STRING == 0 {
-			return types.NewInt(int64(v))
+		d.skipWhitespace()
+		if d.pos >= len(d.data) || d.data[d.pos] != ':' {
+			return nil, d.syntaxError()
 		}
-		return types.NewFloat(v)
+		d.pos++
+		d.skipWhitespace()
 
-	case string:
-		return types.NewString(v)
+		value, err := d.parseValue(depth)
+		if err != nil {
+			return nil, err
+		}
 
-	case []interface{}:
-		// JSON array
-		arr := types.NewEmptyArray()
-		for _, item := range v {
-			arr.Append(convertFromJSON(item, associative, flags, currentDepth+1, maxDepth))
+		if arr != nil {
+			arr.Set(types.NewString(key), value)
+		} else {
+			// Property names starting with NUL are reserved for mangled names
+			if strings.HasPrefix(key, "\x00") {
+				return nil, &Error{Code: JSON_ERROR_INVALID_PROPERTY_NAME}
+			}
+			obj.AddProperty(key, &types.Property{Value: value, Visibility: types.VisibilityPublic})
+		}
+
+		d.skipWhitespace()
+		if d.pos >= len(d.data) {
+			return nil, d.syntaxError()
+		}
+		switch d.data[d.pos] {
+		case ',':
+			d.pos++
+		case '}':
+			d.pos++
+			return result(), nil
+		default:
+			return nil, d.syntaxError()
 		}
-		return types.NewArray(arr)
+	}
+}
 
-	case map[string]interface{}:
-		// JSON object
-		if associative || flags&JSON_OBJECT_AS_ARRAY != 0 {
-			// Return as associative array
-			arr := types.NewEmptyArray()
-			for key, value := range v {
-				arr.Set(
-					types.NewString(key),
-					convertFromJSON(value, associative, flags, currentDepth+1, maxDepth),
-				)
+// parseString parses a quoted string, decoding escapes and validating UTF-8
+func (d *Decoder) parseString() (string, error) {
+	d.pos++ // opening quote
+	var out strings.Builder
+
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+		switch {
+		case c == '"':
+			d.pos++
+			return out.String(), nil
+
+		case c == '\\':
+			if err := d.parseEscape(&out); err != nil {
+				return "", err
 			}
-			return types.NewArray(arr)
-		} else {
-			// Return as object (stdClass)
-			class := types.NewClassEntry("stdClass")
-			obj := types.NewObjectFromClass(class)
-
-			for key, value := range v {
-				// Add property to object
-				propDef := &types.PropertyDef{
-					Name:       key,
-					Visibility: types.VisibilityPublic,
-					IsStatic:   false,
-					Type:       "",
-					HasDefault: false,
-					Default:    nil,
-					IsReadOnly: false,
-				}
-				obj.ClassEntry.Properties[key] = propDef
-
-				prop := &types.Property{
-					Value:      convertFromJSON(value, associative, flags, currentDepth+1, maxDepth),
-					Visibility: types.VisibilityPublic,
-					IsStatic:   false,
-					Type:       "",
-					HasDefault: false,
-					Default:    nil,
-					IsReadOnly: false,
+
+		case c < 0x20:
+			return "", &Error{Code: JSON_ERROR_CTRL_CHAR}
+
+		case c < utf8.RuneSelf:
+			out.WriteByte(c)
+			d.pos++
+
+		default:
+			r, size := utf8.DecodeRuneInString(d.data[d.pos:])
+			if r == utf8.RuneError && size <= 1 {
+				switch {
+				case d.Options&JSON_INVALID_UTF8_SUBSTITUTE != 0:
+					out.WriteString("\uFFFD")
+				case d.Options&JSON_INVALID_UTF8_IGNORE != 0:
+				default:
+					return "", &Error{Code: JSON_ERROR_UTF8}
 				}
-				obj.Properties[key] = prop
+				d.pos++
+				continue
 			}
+			out.WriteString(d.data[d.pos : d.pos+size])
+			d.pos += size
+		}
+	}
+	return "", &Error{Code: JSON_ERROR_CTRL_CHAR}
+}
 
-			return types.NewObject(obj)
+// parseEscape decodes the escape sequence at the current position
+func (d *Decoder) parseEscape(out *strings.Builder) error {
+	if d.pos+1 >= len(d.data) {
+		return &Error{Code: JSON_ERROR_SYNTAX}
+	}
+	c := d.data[d.pos+1]
+	d.pos += 2
+
+	switch c {
+	case '"', '\\', '/':
+		out.WriteByte(c)
+	case 'b':
+		out.WriteByte('\b')
+	case 'f':
+		out.WriteByte('\f')
+	case 'n':
+		out.WriteByte('\n')
+	case 'r':
+		out.WriteByte('\r')
+	case 't':
+		out.WriteByte('\t')
+	case 'u':
+		r, ok := d.parseHex()
+		if !ok {
+			return &Error{Code: JSON_ERROR_SYNTAX}
 		}
+		if utf16.IsSurrogate(r) {
+			// A high surrogate must be followed by an escaped low surrogate
+			if r >= 0xDC00 || !strings.HasPrefix(d.data[d.pos:], "\\u") {
+				return &Error{Code: JSON_ERROR_UTF16}
+			}
+			d.pos += 2
+			lo, ok := d.parseHex()
+			if !ok {
+				return &Error{Code: JSON_ERROR_SYNTAX}
+			}
+			if r = utf16.DecodeRune(r, lo); r == utf8.RuneError {
+				return &Error{Code: JSON_ERROR_UTF16}
+			}
+		}
+		out.WriteRune(r)
+	default:
+		return &Error{Code: JSON_ERROR_SYNTAX}
+	}
+	return nil
+}
 
+// parseHex parses the four hex digits of a \u escape
+func (d *Decoder) parseHex() (rune, bool) {
+	if d.pos+4 > len(d.data) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(d.data[d.pos:d.pos+4], 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	d.pos += 4
+	return rune(n), true
+}
+
+// parseNumber parses a number: an int if it has no fraction or exponent
+// and fits, otherwise a float (or a string with JSON_BIGINT_AS_STRING)
+func (d *Decoder) parseNumber() (*types.Value, error) {
+	start := d.pos
+	isFloat := false
+
+	if d.data[d.pos] == '-' {
+		d.pos++
+	}
+	switch {
+	case d.pos < len(d.data) && d.data[d.pos] == '0':
+		d.pos++
+	case d.pos < len(d.data) && d.data[d.pos] >= '1' && d.data[d.pos] <= '9':
+		d.skipDigits()
 	default:
-		return types.NewNull()
+		return nil, d.syntaxError()
+	}
+
+	if d.pos < len(d.data) && d.data[d.pos] == '.' {
+		isFloat = true
+		d.pos++
+		if !d.skipDigits() {
+			return nil, d.syntaxError()
+		}
+	}
+	if d.pos < len(d.data) && (d.data[d.pos] == 'e' || d.data[d.pos] == 'E') {
+		isFloat = true
+		d.pos++
+		if d.pos < len(d.data) && (d.data[d.pos] == '+' || d.data[d.pos] == '-') {
+			d.pos++
+		}
+		if !d.skipDigits() {
+			return nil, d.syntaxError()
+		}
+	}
+
+	text := d.data[start:d.pos]
+	if !isFloat {
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return types.NewInt(n), nil
+		}
+		if d.Options&JSON_BIGINT_AS_STRING != 0 {
+			return types.NewString(text), nil
+		}
 	}
+	f, _ := strconv.ParseFloat(text, 64)
+	return types.NewFloat(f), nil
+}
+
+// skipDigits advances past decimal digits and reports whether there were any
+func (d *Decoder) skipDigits() bool {
+	start := d.pos
+	for d.pos < len(d.data) && d.data[d.pos] >= '0' && d.data[d.pos] <= '9' {
+		d.pos++
+	}
+	return d.pos > start
+}
+
+// JsonDecode decodes a JSON string
+// json_decode(string $json, ?bool $associative = null, int $depth = 512, int $flags = 0): mixed
+func JsonDecode(jsonStr *types.Value, args ...*types.Value) *types.Value {
+	associative := false
+	depth := DefaultDepth
+	flags := 0
+
+	if len(args) > 0 && args[0] != nil {
+		associative = args[0].ToBool()
+	}
+
+	if len(args) > 1 && args[1] != nil {
+		depth = int(args[1].ToInt())
+	}
+
+	if len(args) > 2 && args[2] != nil {
+		flags = int(args[2].ToInt())
+	}
+
+	result, _ := Decode(jsonStr.ToString(), associative, depth, flags&^JSON_THROW_ON_ERROR)
+	return result
+}
+
+// Decode decodes a JSON string for json_decode(). Errors are recorded for
+// json_last_error() and give null, unless JSON_THROW_ON_ERROR is set, in
+// which case they are returned.
+func Decode(data string, associative bool, depth, flags int) (*types.Value, error) {
+	result, err := NewDecoder(associative, depth, flags).Decode(data)
+	if flags&JSON_THROW_ON_ERROR != 0 {
+		return result, err
+	}
+	if err != nil {
+		lastJsonError.Store(int64(errorCode(err)))
+		return types.NewNull(), nil
+	}
+	lastJsonError.Store(JSON_ERROR_NONE)
+	return result, nil
 }
 
 // ============================================================================
@@ -427,49 +908,41 @@ func convertFromJSON(val interface{}, associative bool, flags int, currentDepth
 // JsonLastError returns the last error occurred
 // json_last_error(): int
 func JsonLastError() *types.Value {
-	return types.NewInt(int64(lastJsonError))
+	return types.NewInt(lastJsonError.Load())
 }
 
 // JsonLastErrorMsg returns the error message of the last json_encode() or json_decode() call
 // json_last_error_msg(): string
 func JsonLastErrorMsg() *types.Value {
-	switch lastJsonError {
+	return types.NewString(errorMessage(int(lastJsonError.Load())))
+}
+
+// errorMessage returns the message of a JSON_ERROR_* code
+func errorMessage(code int) string {
+	switch code {
 	case JSON_ERROR_NONE:
-		return types.NewString("No error")
+		return "No error"
 	case JSON_ERROR_DEPTH:
-		return types.NewString("Maximum stack depth exceeded")
+		return "Maximum stack depth exceeded"
 	case JSON_ERROR_STATE_MISMATCH:
-		return types.NewString("State mismatch (invalid or malformed JSON)")
+		return "State mismatch (invalid or malformed JSON)"
 	case JSON_ERROR_CTRL_CHAR:
-		return types.NewString("Control character error, possibly incorrectly encoded")
+		return "Control character error, possibly incorrectly encoded"
 	case JSON_ERROR_SYNTAX:
-		return types.NewString("Syntax error")
+		return "Syntax error"
 	case JSON_ERROR_UTF8:
-		return types.NewString("Malformed UTF-8 characters, possibly incorrectly encoded")
+		return "Malformed UTF-8 characters, possibly incorrectly encoded"
 	case JSON_ERROR_RECURSION:
-		return types.NewString("Recursion detected")
+		return "Recursion detected"
 	case JSON_ERROR_INF_OR_NAN:
-		return types.NewString("Inf and NaN cannot be JSON encoded")
+		return "Inf and NaN cannot be JSON encoded"
 	case JSON_ERROR_UNSUPPORTED_TYPE:
-		return types.NewString("Type is not supported")
+		return "Type is not supported"
+	case JSON_ERROR_INVALID_PROPERTY_NAME:
+		return "The decoded property name is invalid"
+	case JSON_ERROR_UTF16:
+		return "Single unpaired UTF-16 surrogate in unicode escape"
 	default:
-		return types.NewString("Unknown error")
-	}
-}
-
-// ============================================================================
-// Utility Functions
-// ============================================================================
-
-// isFinite checks if a float is finite (not NaN or Inf)
-func isFinite(f float64) bool {
-	// Check for NaN
-	if f != f {
-		return false
-	}
-	// Check for infinity
-	if f > 1.7976931348623157e+308 || f < -1.7976931348623157e+308 {
-		return false
+		return "Unknown error"
 	}
-	return true
 }
@@ -1,6 +1,7 @@
 package json
 
 import (
+	"math"
 	"strings"
 	"testing"
 
@@ -61,6 +62,10 @@ func TestJsonEncodeFloat(t *testing.T) {
 		{types.NewFloat(3.14), "3.14"},
 		{types.NewFloat(0.0), "0"},
 		{types.NewFloat(-2.5), "-2.5"},
+		{types.NewFloat(0.30000000000000004), "0.30000000000000004"},
+		{types.NewFloat(1e25), "1.0e+25"},
+		{types.NewFloat(1.5e-7), "1.5e-7"},
+		{types.NewFloat(math.Copysign(0, -1)), "-0"},
 	}
 
 	for _, tt := range tests {
@@ -465,7 +470,7 @@ func TestJsonDecodeUnicode(t *testing.T) {
 		t.Errorf("JsonDecode unicode should return string")
 	}
 
-	// Go's JSON decoder handles unicode automatically
+	// Unicode escapes are decoded to UTF-8
 	str := result.ToString()
 	if !strings.Contains(str, "A") {
 		t.Errorf("JsonDecode should decode unicode escape, got %v", str)
@@ -481,3 +486,149 @@ func TestJsonEncodeEmptyObject(t *testing.T) {
 		t.Errorf("JsonEncode(empty, JSON_FORCE_OBJECT) = %v, want '{}'", result.ToString())
 	}
 }
+
+func TestJsonEncodePrettyPrint(t *testing.T) {
+	inner := types.NewArrayFromSlice([]*types.Value{types.NewInt(1), types.NewInt(2)})
+	arr := types.NewEmptyArray()
+	arr.Set(types.NewString("a"), types.NewArray(inner))
+	arr.Set(types.NewString("b"), types.NewArray(types.NewEmptyArray()))
+
+	result := JsonEncode(types.NewArray(arr), types.NewInt(JSON_PRETTY_PRINT))
+	expected := "{\n    \"a\": [\n        1,\n        2\n    ],\n    \"b\": []\n}"
+	if result.ToString() != expected {
+		t.Errorf("JsonEncode with JSON_PRETTY_PRINT = %q, want %q", result.ToString(), expected)
+	}
+}
+
+func TestJsonEncodeUnicode(t *testing.T) {
+	str := types.NewString("é😀")
+
+	if result := JsonEncode(str); result.ToString() != `"\u00e9\ud83d\ude00"` {
+		t.Errorf("JsonEncode should escape non-ASCII as UTF-16, got %v", result.ToString())
+	}
+	if result := JsonEncode(str, types.NewInt(JSON_UNESCAPED_UNICODE)); result.ToString() != `"é😀"` {
+		t.Errorf("JsonEncode with JSON_UNESCAPED_UNICODE = %v", result.ToString())
+	}
+
+	result := JsonEncode(types.NewString("a\xffb"))
+	if result.Type() != types.TypeBool || JsonLastError().ToInt() != JSON_ERROR_UTF8 {
+		t.Errorf("JsonEncode of invalid UTF-8 should fail with JSON_ERROR_UTF8, got %v", result)
+	}
+	result = JsonEncode(types.NewString("a\xffb"), types.NewInt(JSON_INVALID_UTF8_IGNORE))
+	if result.ToString() != `"ab"` {
+		t.Errorf("JsonEncode with JSON_INVALID_UTF8_IGNORE = %v, want \"ab\"", result.ToString())
+	}
+}
+
+func TestJsonEncodeDepth(t *testing.T) {
+	inner := types.NewArrayFromSlice([]*types.Value{types.NewInt(1)})
+	outer := types.NewArray(types.NewArrayFromSlice([]*types.Value{types.NewArray(inner)}))
+
+	if result := JsonEncode(outer, types.NewInt(0), types.NewInt(2)); result.ToString() != "[[1]]" {
+		t.Errorf("JsonEncode at depth 2 = %v, want [[1]]", result.ToString())
+	}
+	result := JsonEncode(outer, types.NewInt(0), types.NewInt(1))
+	if result.Type() != types.TypeBool || JsonLastError().ToInt() != JSON_ERROR_DEPTH {
+		t.Errorf("JsonEncode beyond the depth should fail with JSON_ERROR_DEPTH, got %v", result)
+	}
+}
+
+func TestJsonEncodeErrors(t *testing.T) {
+	nan := types.NewFloat(math.NaN())
+	if result := JsonEncode(nan); result.Type() != types.TypeBool || JsonLastError().ToInt() != JSON_ERROR_INF_OR_NAN {
+		t.Errorf("JsonEncode(NAN) should fail with JSON_ERROR_INF_OR_NAN, got %v", result)
+	}
+
+	arr := types.NewArrayFromSlice([]*types.Value{types.NewInt(1), nan})
+	result := JsonEncode(types.NewArray(arr), types.NewInt(JSON_PARTIAL_OUTPUT_ON_ERROR))
+	if result.ToString() != "[1,0]" || JsonLastError().ToInt() != JSON_ERROR_INF_OR_NAN {
+		t.Errorf("JsonEncode with JSON_PARTIAL_OUTPUT_ON_ERROR = %v, want [1,0]", result)
+	}
+
+	obj := types.NewObjectInstance("Node")
+	obj.Properties["self"] = &types.Property{Value: types.NewObject(obj), Visibility: types.VisibilityPublic}
+	if result := JsonEncode(types.NewObject(obj)); result.Type() != types.TypeBool || JsonLastError().ToInt() != JSON_ERROR_RECURSION {
+		t.Errorf("JsonEncode of a recursive object should fail with JSON_ERROR_RECURSION, got %v", result)
+	}
+
+	if _, err := Encode(nan, JSON_THROW_ON_ERROR, DefaultDepth, nil); err == nil {
+		t.Error("Encode with JSON_THROW_ON_ERROR should return the error")
+	}
+}
+
+func TestJsonEncodePublicPropertiesOnly(t *testing.T) {
+	obj := types.NewObjectInstance("User")
+	obj.Properties["name"] = &types.Property{Value: types.NewString("ann"), Visibility: types.VisibilityPublic}
+	obj.Properties["password"] = &types.Property{Value: types.NewString("x"), Visibility: types.VisibilityPrivate}
+
+	if result := JsonEncode(types.NewObject(obj)); result.ToString() != `{"name":"ann"}` {
+		t.Errorf("JsonEncode(object) = %v, want only public properties", result.ToString())
+	}
+}
+
+func TestJsonEncodeSerializer(t *testing.T) {
+	obj := types.NewObjectInstance("Money")
+	serialize := func(o *types.Object) (*types.Value, bool, error) {
+		return types.NewString("1.00 EUR"), true, nil
+	}
+	result, _ := NewEncoder(0, DefaultDepth, serialize).Encode(types.NewObject(obj))
+	if result != `"1.00 EUR"` {
+		t.Errorf("Encoder should encode the serialized value, got %v", result)
+	}
+}
+
+func TestJsonDecodeKeyOrder(t *testing.T) {
+	result := JsonDecode(types.NewString(`{"b":1,"a":2,"10":3}`), types.NewBool(true))
+
+	var keys []string
+	result.ToArray().Each(func(key, _ *types.Value) bool {
+		keys = append(keys, key.ToString())
+		return true
+	})
+	if strings.Join(keys, ",") != "b,a,10" {
+		t.Errorf("JsonDecode should keep the key order, got %v", keys)
+	}
+	if v, _ := result.ToArray().Get(types.NewInt(10)); v == nil || v.ToInt() != 3 {
+		t.Error("JsonDecode should store numeric keys as integers")
+	}
+}
+
+func TestJsonDecodeNumbers(t *testing.T) {
+	if result := JsonDecode(types.NewString("1.0")); result.Type() != types.TypeFloat {
+		t.Errorf("JsonDecode('1.0') should return a float, got %v", result.Type())
+	}
+	if result := JsonDecode(types.NewString("12345678901234567890")); result.Type() != types.TypeFloat {
+		t.Errorf("JsonDecode of a big int should return a float, got %v", result.Type())
+	}
+	result := JsonDecode(types.NewString("12345678901234567890"), nil, nil, types.NewInt(JSON_BIGINT_AS_STRING))
+	if result.ToString() != "12345678901234567890" || result.Type() != types.TypeString {
+		t.Errorf("JsonDecode with JSON_BIGINT_AS_STRING = %v, want the digits as a string", result)
+	}
+}
+
+func TestJsonDecodeErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		depth int64
+		code  int
+	}{
+		{"", 512, JSON_ERROR_SYNTAX},
+		{"[1,]", 512, JSON_ERROR_SYNTAX},
+		{"1 2", 512, JSON_ERROR_SYNTAX},
+		{"\"a\tb\"", 512, JSON_ERROR_CTRL_CHAR},
+		{"\"\xff\"", 512, JSON_ERROR_UTF8},
+		{`"\ud800"`, 512, JSON_ERROR_UTF16},
+		{"[[1]]", 1, JSON_ERROR_DEPTH},
+		{"{\"\\u0000a\":1}", 512, JSON_ERROR_INVALID_PROPERTY_NAME},
+	}
+	for _, tt := range tests {
+		result := JsonDecode(types.NewString(tt.input), nil, types.NewInt(tt.depth))
+		if !result.IsNull() || JsonLastError().ToInt() != int64(tt.code) {
+			t.Errorf("JsonDecode(%q) = %v with error %v, want null with %d", tt.input, result, JsonLastError(), tt.code)
+		}
+	}
+
+	if _, err := Decode("[", false, DefaultDepth, JSON_THROW_ON_ERROR); err == nil {
+		t.Error("Decode with JSON_THROW_ON_ERROR should return the error")
+	}
+}
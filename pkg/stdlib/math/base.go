@@ -0,0 +1,139 @@
+package math
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Base Conversion
+// ============================================================================
+
+// parseBase converts a number in base to an int, or to a float once it
+// overflows. Characters that are not digits of the base are ignored, as
+// is a 0b, 0o or 0x prefix matching the base.
+func parseBase(s string, base int) *types.Value {
+	s = strings.TrimSpace(s)
+	if len(s) > 2 && s[0] == '0' {
+		prefix := s[1] | 0x20
+		if (base == 2 && prefix == 'b') || (base == 8 && prefix == 'o') || (base == 16 && prefix == 'x') {
+			s = s[2:]
+		}
+	}
+
+	var n int64
+	var f float64
+	overflow := false
+	for i := 0; i < len(s); i++ {
+		digit := digitValue(s[i])
+		if digit >= base {
+			continue
+		}
+		if !overflow {
+			if n > (math.MaxInt64-int64(digit))/int64(base) {
+				overflow = true
+				f = float64(n)
+			} else {
+				n = n*int64(base) + int64(digit)
+				continue
+			}
+		}
+		f = f*float64(base) + float64(digit)
+	}
+	if overflow {
+		return types.NewFloat(f)
+	}
+	return types.NewInt(n)
+}
+
+// digitValue returns the value of a digit in bases up to 36, or 36 for
+// other characters
+func digitValue(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'z':
+		return int(c-'a') + 10
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10
+	}
+	return 36
+}
+
+// formatBase formats a non-negative number in base; floats beyond the
+// integer range are converted digit by digit
+func formatBase(v *types.Value, base int) string {
+	if v.IsInt() {
+		return strconv.FormatUint(uint64(v.ToInt()), base)
+	}
+	f := math.Floor(math.Abs(v.ToFloat()))
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return "0"
+	}
+	var digits []byte
+	for {
+		digit := int(math.Mod(f, float64(base)))
+		digits = append(digits, "0123456789abcdefghijklmnopqrstuvwxyz"[digit])
+		f = math.Floor(f / float64(base))
+		if f < 1 {
+			break
+		}
+	}
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		digits[i], digits[j] = digits[j], digits[i]
+	}
+	return string(digits)
+}
+
+// BaseConvert converts a number between arbitrary bases
+// base_convert(string $num, int $from_base, int $to_base): string
+func BaseConvert(num, fromBase, toBase *types.Value) (*types.Value, error) {
+	from, to := int(fromBase.ToInt()), int(toBase.ToInt())
+	if from < 2 || from > 36 {
+		return nil, valueError("base_convert(): Argument #2 ($from_base) must be between 2 and 36 (inclusive)")
+	}
+	if to < 2 || to > 36 {
+		return nil, valueError("base_convert(): Argument #3 ($to_base) must be between 2 and 36 (inclusive)")
+	}
+	return types.NewString(formatBase(parseBase(num.ToString(), from), to)), nil
+}
+
+// Bindec converts a binary string to a number
+// bindec(string $binary_string): int|float
+func Bindec(s *types.Value) *types.Value {
+	return parseBase(s.ToString(), 2)
+}
+
+// Octdec converts an octal string to a number
+// octdec(string $octal_string): int|float
+func Octdec(s *types.Value) *types.Value {
+	return parseBase(s.ToString(), 8)
+}
+
+// Hexdec converts a hexadecimal string to a number
+// hexdec(string $hex_string): int|float
+func Hexdec(s *types.Value) *types.Value {
+	return parseBase(s.ToString(), 16)
+}
+
+// Decbin converts an integer to binary; negative numbers are formatted as
+// their unsigned two's complement
+// decbin(int $num): string
+func Decbin(num *types.Value) *types.Value {
+	return types.NewString(strconv.FormatUint(uint64(num.ToInt()), 2))
+}
+
+// Decoct converts an integer to octal
+// decoct(int $num): string
+func Decoct(num *types.Value) *types.Value {
+	return types.NewString(strconv.FormatUint(uint64(num.ToInt()), 8))
+}
+
+// Dechex converts an integer to hexadecimal
+// dechex(int $num): string
+func Dechex(num *types.Value) *types.Value {
+	return types.NewString(strconv.FormatUint(uint64(num.ToInt()), 16))
+}
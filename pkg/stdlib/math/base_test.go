@@ -0,0 +1,86 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestBaseConvert(t *testing.T) {
+	tests := []struct {
+		num      string
+		from, to int64
+		expected string
+	}{
+		{"ff", 16, 2, "11111111"},
+		{"A37334", 16, 2, "101000110111001100110100"},
+		{"255", 10, 36, "73"},
+		{"zz", 36, 10, "1295"},
+		{"0x1f", 16, 10, "31"},
+		{"1g2", 16, 10, "18"},
+		{"", 10, 2, "0"},
+	}
+
+	for _, tt := range tests {
+		result, err := BaseConvert(types.NewString(tt.num), types.NewInt(tt.from), types.NewInt(tt.to))
+		if err != nil {
+			t.Fatalf("BaseConvert(%q, %d, %d) error: %v", tt.num, tt.from, tt.to, err)
+		}
+		if result.ToString() != tt.expected {
+			t.Errorf("BaseConvert(%q, %d, %d) = %q, want %q", tt.num, tt.from, tt.to, result.ToString(), tt.expected)
+		}
+	}
+}
+
+func TestBaseConvertInvalidBase(t *testing.T) {
+	_, err := BaseConvert(types.NewString("10"), types.NewInt(1), types.NewInt(10))
+	if e, ok := err.(*Error); !ok || e.Class != "ValueError" {
+		t.Errorf("BaseConvert with base 1 error = %v, want ValueError", err)
+	}
+	_, err = BaseConvert(types.NewString("10"), types.NewInt(10), types.NewInt(37))
+	if e, ok := err.(*Error); !ok || e.Message != "base_convert(): Argument #3 ($to_base) must be between 2 and 36 (inclusive)" {
+		t.Errorf("BaseConvert with base 37 error = %v", err)
+	}
+}
+
+func TestToDecimal(t *testing.T) {
+	tests := []struct {
+		fn       func(*types.Value) *types.Value
+		input    string
+		expected string
+	}{
+		{Bindec, "110011", "51"},
+		{Bindec, "0b101", "5"},
+		{Bindec, "1111111111111111111111111111111111111111111111111111111111111111", "1.844674407371E+19"},
+		{Octdec, "777", "511"},
+		{Hexdec, "ff", "255"},
+		{Hexdec, "0xFF", "255"},
+		{Hexdec, "7fffffffffffffff", "9223372036854775807"},
+	}
+
+	for _, tt := range tests {
+		if result := tt.fn(types.NewString(tt.input)); result.ToString() != tt.expected {
+			t.Errorf("converting %q = %s, want %s", tt.input, result.ToString(), tt.expected)
+		}
+	}
+}
+
+func TestFromDecimal(t *testing.T) {
+	tests := []struct {
+		fn       func(*types.Value) *types.Value
+		input    int64
+		expected string
+	}{
+		{Decbin, 12, "1100"},
+		{Decbin, -1, "1111111111111111111111111111111111111111111111111111111111111111"},
+		{Decoct, 264, "410"},
+		{Dechex, 255, "ff"},
+		{Dechex, -1, "ffffffffffffffff"},
+	}
+
+	for _, tt := range tests {
+		if result := tt.fn(types.NewInt(tt.input)); result.ToString() != tt.expected {
+			t.Errorf("converting %d = %s, want %s", tt.input, result.ToString(), tt.expected)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package math
+
+import (
+	"math"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Math Constants
+// ============================================================================
+
+// Rounding modes of round()
+const (
+	PHP_ROUND_HALF_UP   = 1
+	PHP_ROUND_HALF_DOWN = 2
+	PHP_ROUND_HALF_EVEN = 3
+	PHP_ROUND_HALF_ODD  = 4
+)
+
+// Seeding modes of mt_srand()
+const (
+	MT_RAND_MT19937 = 0
+	MT_RAND_PHP     = 1
+)
+
+// Constants returns the constants defined by the math extension and the
+// engine's integer and float limits
+func Constants() map[string]*types.Value {
+	return map[string]*types.Value{
+		"M_PI":       types.NewFloat(math.Pi),
+		"M_E":        types.NewFloat(math.E),
+		"M_LOG2E":    types.NewFloat(math.Log2E),
+		"M_LOG10E":   types.NewFloat(math.Log10E),
+		"M_LN2":      types.NewFloat(math.Ln2),
+		"M_LN10":     types.NewFloat(math.Ln10),
+		"M_PI_2":     types.NewFloat(math.Pi / 2),
+		"M_PI_4":     types.NewFloat(math.Pi / 4),
+		"M_1_PI":     types.NewFloat(1 / math.Pi),
+		"M_2_PI":     types.NewFloat(2 / math.Pi),
+		"M_SQRTPI":   types.NewFloat(math.SqrtPi),
+		"M_2_SQRTPI": types.NewFloat(2 / math.SqrtPi),
+		"M_LNPI":     types.NewFloat(math.Log(math.Pi)),
+		"M_EULER":    types.NewFloat(0.57721566490153286061),
+		"M_SQRT2":    types.NewFloat(math.Sqrt2),
+		"M_SQRT1_2":  types.NewFloat(1 / math.Sqrt2),
+		"M_SQRT3":    types.NewFloat(math.Sqrt(3)),
+		"NAN":        types.NewFloat(math.NaN()),
+		"INF":        types.NewFloat(math.Inf(1)),
+
+		"PHP_ROUND_HALF_UP":   types.NewInt(PHP_ROUND_HALF_UP),
+		"PHP_ROUND_HALF_DOWN": types.NewInt(PHP_ROUND_HALF_DOWN),
+		"PHP_ROUND_HALF_EVEN": types.NewInt(PHP_ROUND_HALF_EVEN),
+		"PHP_ROUND_HALF_ODD":  types.NewInt(PHP_ROUND_HALF_ODD),
+		"MT_RAND_MT19937":     types.NewInt(MT_RAND_MT19937),
+		"MT_RAND_PHP":         types.NewInt(MT_RAND_PHP),
+
+		"PHP_INT_MAX":       types.NewInt(math.MaxInt64),
+		"PHP_INT_MIN":       types.NewInt(math.MinInt64),
+		"PHP_INT_SIZE":      types.NewInt(8),
+		"PHP_FLOAT_EPSILON": types.NewFloat(2.220446049250313e-16),
+		"PHP_FLOAT_MAX":     types.NewFloat(math.MaxFloat64),
+		"PHP_FLOAT_MIN":     types.NewFloat(2.2250738585072014e-308),
+		"PHP_FLOAT_DIG":     types.NewInt(15),
+	}
+}
@@ -3,37 +3,59 @@ package math
 import (
 	"fmt"
 	"math"
-	"math/rand"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/krizos/php-go/pkg/types"
 )
 
-// Initialize random seed
-func init() {
-	rand.Seed(time.Now().UnixNano())
+// Error is an error thrown by a math function, such as the
+// DivisionByZeroError of intdiv()
+type Error struct {
+	Class   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func valueError(format string, args ...interface{}) error {
+	return &Error{Class: "ValueError", Message: fmt.Sprintf(format, args...)}
+}
+
+// number converts a value to an int or float the way arithmetic does:
+// numeric strings become their number, null and booleans an int
+func number(v *types.Value) *types.Value {
+	switch v.Type() {
+	case types.TypeInt, types.TypeFloat:
+		return v
+	case types.TypeString:
+		n, _ := types.ParseNumeric(v.ToString())
+		return n
+	}
+	return types.NewInt(v.ToInt())
 }
 
 // ============================================================================
 // Basic Math Functions
 // ============================================================================
 
-// Abs returns the absolute value of a number
+// Abs returns the absolute value of a number; abs(PHP_INT_MIN) is a float
 // abs(int|float $num): int|float
 func Abs(num *types.Value) *types.Value {
+	num = number(num)
 	if num.Type() == types.TypeInt {
 		n := num.ToInt()
+		if n == math.MinInt64 {
+			return types.NewFloat(-float64(n))
+		}
 		if n < 0 {
 			return types.NewInt(-n)
 		}
 		return types.NewInt(n)
 	}
-
-	// Float or convertible to float
-	f := num.ToFloat()
-	return types.NewFloat(math.Abs(f))
+	return types.NewFloat(math.Abs(num.ToFloat()))
 }
 
 // Ceil rounds a number up to the next highest integer
@@ -50,159 +72,155 @@ func Floor(num *types.Value) *types.Value {
 	return types.NewFloat(math.Floor(f))
 }
 
-// Round rounds a float to specified precision
-// round(int|float $num, int $precision = 0): float
-func Round(num *types.Value, precision ...*types.Value) *types.Value {
-	f := num.ToFloat()
-
-	// Default precision is 0
-	prec := 0
-	if len(precision) > 0 && precision[0] != nil {
-		prec = int(precision[0].ToInt())
+// Round rounds a number to a precision (decimal digits after the point,
+// negative for digits before it). Halves are rounded according to mode,
+// away from zero by default. The number is rounded as its shortest decimal
+// representation, so round(1.005, 2) is 1.01 although the float is
+// slightly below 1.005.
+// round(int|float $num, int $precision = 0, int $mode = PHP_ROUND_HALF_UP): float
+func Round(num *types.Value, args ...*types.Value) (*types.Value, error) {
+	precision := 0
+	if len(args) > 0 && args[0] != nil {
+		precision = int(args[0].ToInt())
 	}
+	mode := PHP_ROUND_HALF_UP
+	if len(args) > 1 && args[1] != nil {
+		mode = int(args[1].ToInt())
+		if mode < PHP_ROUND_HALF_UP || mode > PHP_ROUND_HALF_ODD {
+			return nil, valueError("round(): Argument #3 ($mode) must be a valid rounding mode (PHP_ROUND_*)")
+		}
+	}
+	return types.NewFloat(roundFloat(number(num).ToFloat(), precision, mode)), nil
+}
 
-	if prec == 0 {
-		return types.NewFloat(math.Round(f))
+// roundFloat rounds f to precision decimal places
+func roundFloat(f float64, precision, mode int) float64 {
+	if f == 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return f
+	}
+	negative := f < 0
+
+	// The shortest representation d.ddd × 10^exp as digits and exponent
+	repr := strconv.FormatFloat(math.Abs(f), 'e', -1, 64)
+	mantissa, expPart, _ := strings.Cut(repr, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	exp, _ := strconv.Atoi(expPart)
+
+	// Number of digits kept
+	keep := exp + 1 + precision
+	if keep >= len(digits) {
+		return f
 	}
 
-	// Round to specified decimal places
-	shift := math.Pow(10, float64(prec))
-	return types.NewFloat(math.Round(f*shift) / shift)
-}
+	var kept string
+	var rest string
+	if keep > 0 {
+		kept, rest = digits[:keep], digits[keep:]
+	} else if keep == 0 {
+		rest = digits
+	} else {
+		return math.Copysign(0, f)
+	}
 
-// Min returns the lowest value
-// min(mixed ...$values): mixed
-func Min(values ...*types.Value) *types.Value {
-	if len(values) == 0 {
-		return types.NewNull()
+	// Compare the dropped digits with one half
+	var cmp int
+	switch {
+	case rest[0] > '5':
+		cmp = 1
+	case rest[0] < '5':
+		cmp = -1
+	case strings.TrimRight(rest[1:], "0") != "":
+		cmp = 1
 	}
 
-	if len(values) == 1 {
-		// Single array argument
-		if values[0].Type() == types.TypeArray {
-			arr := values[0].ToArray()
-			if arr.IsEmpty() {
-				return types.NewNull()
-			}
-
-			var minVal *types.Value
-			arr.Each(func(_, val *types.Value) bool {
-				if minVal == nil {
-					minVal = val
-					return true
-				}
-
-				// Compare values
-				if compareValues(val, minVal) < 0 {
-					minVal = val
-				}
-				return true
-			})
-
-			return minVal
+	last := 0
+	if kept != "" {
+		last = int(kept[len(kept)-1] - '0')
+	}
+	up := cmp > 0
+	if cmp == 0 {
+		switch mode {
+		case PHP_ROUND_HALF_UP:
+			up = true
+		case PHP_ROUND_HALF_EVEN:
+			up = last%2 == 1
+		case PHP_ROUND_HALF_ODD:
+			up = last%2 == 0
 		}
-		return values[0]
 	}
 
-	// Multiple arguments
-	minVal := values[0]
-	for i := 1; i < len(values); i++ {
-		if compareValues(values[i], minVal) < 0 {
-			minVal = values[i]
+	if up {
+		kept = incrementDigits(kept)
+	}
+	value, _ := strconv.ParseFloat("0"+kept+"e"+strconv.Itoa(-precision), 64)
+	if negative {
+		value = -value
+	}
+	return value
+}
+
+// incrementDigits adds one to a string of decimal digits
+func incrementDigits(digits string) string {
+	b := []byte(digits)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < '9' {
+			b[i]++
+			return string(b)
 		}
+		b[i] = '0'
 	}
+	return "1" + string(b)
+}
+
+// Min returns the lowest of its arguments, or of the elements of a single
+// array argument, using PHP's comparison rules
+// min(mixed $value, mixed ...$values): mixed
+func Min(values ...*types.Value) (*types.Value, error) {
+	return extreme("min", -1, values)
+}
 
-	return minVal
+// Max returns the highest of its arguments, or of the elements of a
+// single array argument, using PHP's comparison rules
+// max(mixed $value, mixed ...$values): mixed
+func Max(values ...*types.Value) (*types.Value, error) {
+	return extreme("max", 1, values)
 }
 
-// Max returns the highest value
-// max(mixed ...$values): mixed
-func Max(values ...*types.Value) *types.Value {
+// extreme implements min() and max(): sign is -1 to keep the lowest value
+// and 1 to keep the highest; the first of equal values wins
+func extreme(name string, sign int, values []*types.Value) (*types.Value, error) {
 	if len(values) == 0 {
-		return types.NewNull()
+		return nil, &Error{Class: "ArgumentCountError", Message: name + "() expects at least 1 argument, 0 given"}
 	}
-
 	if len(values) == 1 {
-		// Single array argument
-		if values[0].Type() == types.TypeArray {
-			arr := values[0].ToArray()
-			if arr.IsEmpty() {
-				return types.NewNull()
-			}
-
-			var maxVal *types.Value
-			arr.Each(func(_, val *types.Value) bool {
-				if maxVal == nil {
-					maxVal = val
-					return true
-				}
-
-				// Compare values
-				if compareValues(val, maxVal) > 0 {
-					maxVal = val
-				}
-				return true
-			})
-
-			return maxVal
+		if values[0].Type() != types.TypeArray {
+			return nil, &Error{Class: "TypeError", Message: fmt.Sprintf("%s(): Argument #1 ($value) must be of type array, %s given", name, values[0].TypeName())}
 		}
-		return values[0]
-	}
-
-	// Multiple arguments
-	maxVal := values[0]
-	for i := 1; i < len(values); i++ {
-		if compareValues(values[i], maxVal) > 0 {
-			maxVal = values[i]
+		arr := values[0].ToArray()
+		if arr.IsEmpty() {
+			return nil, valueError("%s(): Argument #1 ($value) must contain at least one element", name)
 		}
+		values = values[:0:0]
+		arr.Each(func(_, val *types.Value) bool {
+			values = append(values, val.Deref())
+			return true
+		})
 	}
 
-	return maxVal
-}
-
-// compareValues compares two values for min/max
-// Returns: -1 if a < b, 0 if a == b, 1 if a > b
-func compareValues(a, b *types.Value) int {
-	// If both are numeric, compare numerically
-	if (a.Type() == types.TypeInt || a.Type() == types.TypeFloat) &&
-		(b.Type() == types.TypeInt || b.Type() == types.TypeFloat) {
-		aFloat := a.ToFloat()
-		bFloat := b.ToFloat()
-		if aFloat < bFloat {
-			return -1
-		} else if aFloat > bFloat {
-			return 1
+	result := values[0]
+	for _, val := range values[1:] {
+		if types.Compare(val, result)*sign > 0 {
+			result = val
 		}
-		return 0
-	}
-
-	// String comparison
-	aStr := a.ToString()
-	bStr := b.ToString()
-	if aStr < bStr {
-		return -1
-	} else if aStr > bStr {
-		return 1
 	}
-	return 0
+	return result, nil
 }
 
-// Pow returns base raised to the power of exponent
-// pow(mixed $base, mixed $exp): int|float
+// Pow returns base raised to the power of exp; integer powers stay
+// integers until they overflow
+// pow(mixed $num, mixed $exponent): int|float
 func Pow(base, exp *types.Value) *types.Value {
-	b := base.ToFloat()
-	e := exp.ToFloat()
-
-	result := math.Pow(b, e)
-
-	// Return int if both inputs are ints and result is whole number
-	if base.Type() == types.TypeInt && exp.Type() == types.TypeInt {
-		if result == math.Floor(result) {
-			return types.NewInt(int64(result))
-		}
-	}
-
-	return types.NewFloat(result)
+	return types.Pow(number(base), number(exp))
 }
 
 // Sqrt returns the square root of a number
@@ -283,17 +301,22 @@ func Exp(num *types.Value) *types.Value {
 	return types.NewFloat(math.Exp(num.ToFloat()))
 }
 
-// Log returns the natural logarithm
+// Log returns the logarithm of num in base, the natural logarithm by
+// default
 // log(float $num, float $base = M_E): float
-func Log(num *types.Value, base ...*types.Value) *types.Value {
+func Log(num *types.Value, base ...*types.Value) (*types.Value, error) {
 	n := num.ToFloat()
-
 	if len(base) > 0 && base[0] != nil {
 		b := base[0].ToFloat()
-		return types.NewFloat(math.Log(n) / math.Log(b))
+		if b <= 0 {
+			return nil, valueError("log(): Argument #2 ($base) must be greater than 0")
+		}
+		if b == 1 {
+			return types.NewFloat(math.NaN()), nil
+		}
+		return types.NewFloat(math.Log(n) / math.Log(b)), nil
 	}
-
-	return types.NewFloat(math.Log(n))
+	return types.NewFloat(math.Log(n)), nil
 }
 
 // Log10 returns the base-10 logarithm
@@ -314,115 +337,53 @@ func Expm1(num *types.Value) *types.Value {
 	return types.NewFloat(math.Expm1(num.ToFloat()))
 }
 
-// ============================================================================
-// Random Number Generation
-// ============================================================================
-
-// Rand generates a random integer
-// rand(int $min = 0, int $max = getrandmax()): int
-func Rand(limits ...*types.Value) *types.Value {
-	min := int64(0)
-	max := int64(math.MaxInt32)
-
-	if len(limits) >= 1 && limits[0] != nil {
-		min = limits[0].ToInt()
-	}
-
-	if len(limits) >= 2 && limits[1] != nil {
-		max = limits[1].ToInt()
-	}
-
-	if min > max {
-		min, max = max, min
-	}
-
-	result := min + rand.Int63n(max-min+1)
-	return types.NewInt(result)
-}
-
-// MtRand generates a better random number using Mersenne Twister
-// mt_rand(int $min = 0, int $max = mt_getrandmax()): int
-func MtRand(limits ...*types.Value) *types.Value {
-	// In Go, we use the same rand as Rand (it's already good)
-	return Rand(limits...)
-}
-
-// RandomInt generates a cryptographically secure random integer
-// random_int(int $min, int $max): int
-func RandomInt(min, max *types.Value) *types.Value {
-	minVal := min.ToInt()
-	maxVal := max.ToInt()
-
-	if minVal > maxVal {
-		minVal, maxVal = maxVal, minVal
-	}
-
-	// For simplicity, using math/rand
-	// In production, should use crypto/rand
-	result := minVal + rand.Int63n(maxVal-minVal+1)
-	return types.NewInt(result)
-}
-
-// GetRandMax returns the maximum random number
-// getrandmax(): int
-func GetRandMax() *types.Value {
-	return types.NewInt(math.MaxInt32)
-}
-
-// MtGetRandMax returns the maximum random number for mt_rand
-// mt_getrandmax(): int
-func MtGetRandMax() *types.Value {
-	return types.NewInt(math.MaxInt32)
-}
-
 // ============================================================================
 // Number Formatting
 // ============================================================================
 
-// NumberFormat formats a number with grouped thousands
-// number_format(float $num, int $decimals = 0, string $dec_point = ".", string $thousands_sep = ","): string
+// NumberFormat formats a number with grouped thousands, rounding halves
+// away from zero like round(). Negative decimals round to the left of the
+// decimal point; ints are formatted exactly, however large.
+// number_format(float $num, int $decimals = 0, ?string $decimal_separator = ".", ?string $thousands_separator = ","): string
 func NumberFormat(num *types.Value, args ...*types.Value) *types.Value {
-	n := num.ToFloat()
+	n := number(num)
 
 	decimals := 0
 	decPoint := "."
 	thousandsSep := ","
 
-	// Parse arguments
 	if len(args) >= 1 && args[0] != nil {
 		decimals = int(args[0].ToInt())
 	}
-
-	if len(args) >= 2 && args[1] != nil {
+	if len(args) >= 2 && args[1] != nil && !args[1].IsNull() {
 		decPoint = args[1].ToString()
 	}
-
-	if len(args) >= 3 && args[2] != nil {
+	if len(args) >= 3 && args[2] != nil && !args[2].IsNull() {
 		thousandsSep = args[2].ToString()
 	}
 
-	// Format the number
-	format := "%." + strconv.Itoa(decimals) + "f"
-	formatted := fmt.Sprintf(format, n)
-
-	// Split into integer and decimal parts
-	parts := strings.Split(formatted, ".")
-	intPart := parts[0]
-	var decPart string
-	if len(parts) > 1 {
-		decPart = parts[1]
+	var formatted string
+	if n.IsInt() && decimals >= 0 {
+		formatted = strconv.FormatInt(n.ToInt(), 10)
+		if decimals > 0 {
+			formatted += "." + strings.Repeat("0", decimals)
+		}
+	} else {
+		f := roundFloat(n.ToFloat(), decimals, PHP_ROUND_HALF_UP)
+		if f == 0 {
+			// Never format "-0"
+			f = 0
+		}
+		formatted = strconv.FormatFloat(f, 'f', max(decimals, 0), 64)
 	}
 
-	// Add thousands separator
+	intPart, decPart, _ := strings.Cut(formatted, ".")
 	if thousandsSep != "" {
 		intPart = addThousandsSeparator(intPart, thousandsSep)
 	}
-
-	// Combine parts
 	if decimals > 0 {
 		return types.NewString(intPart + decPoint + decPart)
 	}
-
 	return types.NewString(intPart)
 }
 
@@ -491,18 +452,19 @@ func Fmod(x, y *types.Value) *types.Value {
 	return types.NewFloat(math.Mod(x.ToFloat(), y.ToFloat()))
 }
 
-// Intdiv performs integer division
+// Intdiv performs integer division, truncating towards zero
 // intdiv(int $num1, int $num2): int
-func Intdiv(num1, num2 *types.Value) *types.Value {
+func Intdiv(num1, num2 *types.Value) (*types.Value, error) {
 	n1 := num1.ToInt()
 	n2 := num2.ToInt()
 
 	if n2 == 0 {
-		// Division by zero error
-		return types.NewNull()
+		return nil, &Error{Class: "DivisionByZeroError", Message: "Division by zero"}
 	}
-
-	return types.NewInt(n1 / n2)
+	if n1 == math.MinInt64 && n2 == -1 {
+		return nil, &Error{Class: "ArithmeticError", Message: "Division of PHP_INT_MIN by -1 is not an integer"}
+	}
+	return types.NewInt(n1 / n2), nil
 }
 
 // Fdiv performs floating-point division (PHP 8.0+)
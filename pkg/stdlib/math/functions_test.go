@@ -93,9 +93,9 @@ func TestRound(t *testing.T) {
 	for _, tt := range tests {
 		var result *types.Value
 		if tt.precision == nil {
-			result = Round(tt.input)
+			result, _ = Round(tt.input)
 		} else {
-			result = Round(tt.input, tt.precision)
+			result, _ = Round(tt.input, tt.precision)
 		}
 
 		if result.ToFloat() != tt.expected {
@@ -115,7 +115,7 @@ func TestMin(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		result := Min(tt.values...)
+		result, _ := Min(tt.values...)
 
 		switch exp := tt.expected.(type) {
 		case int64:
@@ -136,12 +136,114 @@ func TestMinWithArray(t *testing.T) {
 	arr.Append(types.NewInt(2))
 	arr.Append(types.NewInt(8))
 
-	result := Min(types.NewArray(arr))
+	result, _ := Min(types.NewArray(arr))
 	if result.ToInt() != 2 {
 		t.Errorf("Min(array) = %v, want 2", result.ToInt())
 	}
 }
 
+func TestRoundModes(t *testing.T) {
+	tests := []struct {
+		num       float64
+		precision int64
+		mode      int64
+		expected  float64
+	}{
+		{2.5, 0, PHP_ROUND_HALF_UP, 3},
+		{-2.5, 0, PHP_ROUND_HALF_UP, -3},
+		{2.5, 0, PHP_ROUND_HALF_DOWN, 2},
+		{-2.5, 0, PHP_ROUND_HALF_DOWN, -2},
+		{2.5, 0, PHP_ROUND_HALF_EVEN, 2},
+		{3.5, 0, PHP_ROUND_HALF_EVEN, 4},
+		{2.5, 0, PHP_ROUND_HALF_ODD, 3},
+		{3.5, 0, PHP_ROUND_HALF_ODD, 3},
+		{1.005, 2, PHP_ROUND_HALF_UP, 1.01},
+		{1.955, 2, PHP_ROUND_HALF_UP, 1.96},
+		{5.045, 2, PHP_ROUND_HALF_EVEN, 5.04},
+		{1241757, -3, PHP_ROUND_HALF_UP, 1242000},
+		{0.285, 2, PHP_ROUND_HALF_UP, 0.29},
+		{9.999, 2, PHP_ROUND_HALF_UP, 10},
+		{0.0004, 2, PHP_ROUND_HALF_UP, 0},
+	}
+
+	for _, tt := range tests {
+		result, err := Round(types.NewFloat(tt.num), types.NewInt(tt.precision), types.NewInt(tt.mode))
+		if err != nil {
+			t.Fatalf("Round(%v, %d, %d) error: %v", tt.num, tt.precision, tt.mode, err)
+		}
+		if result.ToFloat() != tt.expected {
+			t.Errorf("Round(%v, %d, %d) = %v, want %v", tt.num, tt.precision, tt.mode, result.ToFloat(), tt.expected)
+		}
+	}
+}
+
+func TestRoundInvalidMode(t *testing.T) {
+	_, err := Round(types.NewFloat(1.5), types.NewInt(0), types.NewInt(9))
+	if e, ok := err.(*Error); !ok || e.Class != "ValueError" {
+		t.Errorf("Round() with invalid mode error = %v, want ValueError", err)
+	}
+}
+
+func TestMinMaxMixedTypes(t *testing.T) {
+	arr := func(values ...int64) *types.Value {
+		a := types.NewEmptyArray()
+		for _, v := range values {
+			a.Append(types.NewInt(v))
+		}
+		return types.NewArray(a)
+	}
+
+	// want is the index of the argument that must be returned
+	tests := []struct {
+		name   string
+		fn     func(...*types.Value) (*types.Value, error)
+		values []*types.Value
+		want   int
+	}{
+		{"max numeric string", Max, []*types.Value{types.NewString("10"), types.NewInt(9)}, 0},
+		{"max non-numeric string", Max, []*types.Value{types.NewString("abc"), types.NewInt(0)}, 0},
+		{"min non-numeric string", Min, []*types.Value{types.NewString("abc"), types.NewInt(0)}, 1},
+		{"max numeric strings", Max, []*types.Value{types.NewString("10"), types.NewString("9")}, 0},
+		{"max strings", Max, []*types.Value{types.NewString("apple"), types.NewString("banana")}, 1},
+		{"min null", Min, []*types.Value{types.NewInt(-5), types.NewNull()}, 1},
+		{"max bool", Max, []*types.Value{types.NewBool(true), types.NewInt(2)}, 0},
+		{"max array", Max, []*types.Value{types.NewInt(100), arr(1)}, 1},
+		{"max longer array", Max, []*types.Value{arr(5, 6), arr(1, 2, 3)}, 1},
+		{"max equal-size arrays", Max, []*types.Value{arr(1, 3), arr(1, 2)}, 0},
+		{"first of equal values", Max, []*types.Value{types.NewString("1"), types.NewInt(1)}, 0},
+	}
+
+	for _, tt := range tests {
+		result, err := tt.fn(tt.values...)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.name, err)
+		}
+		if result != tt.values[tt.want] {
+			t.Errorf("%s: got %v, want argument #%d", tt.name, result, tt.want+1)
+		}
+	}
+}
+
+func TestMinMaxErrors(t *testing.T) {
+	tests := []struct {
+		values  []*types.Value
+		class   string
+		message string
+	}{
+		{nil, "ArgumentCountError", "min() expects at least 1 argument, 0 given"},
+		{[]*types.Value{types.NewInt(1)}, "TypeError", "min(): Argument #1 ($value) must be of type array, int given"},
+		{[]*types.Value{types.NewArray(types.NewEmptyArray())}, "ValueError", "min(): Argument #1 ($value) must contain at least one element"},
+	}
+
+	for _, tt := range tests {
+		_, err := Min(tt.values...)
+		e, ok := err.(*Error)
+		if !ok || e.Class != tt.class || e.Message != tt.message {
+			t.Errorf("Min(%v) error = %v, want %s: %s", tt.values, err, tt.class, tt.message)
+		}
+	}
+}
+
 func TestMax(t *testing.T) {
 	tests := []struct {
 		values   []*types.Value
@@ -153,7 +255,7 @@ func TestMax(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		result := Max(tt.values...)
+		result, _ := Max(tt.values...)
 
 		switch exp := tt.expected.(type) {
 		case int64:
@@ -300,14 +402,14 @@ func TestExp(t *testing.T) {
 }
 
 func TestLog(t *testing.T) {
-	result := Log(types.NewFloat(math.E))
+	result, _ := Log(types.NewFloat(math.E))
 	if math.Abs(result.ToFloat()-1.0) > 0.0001 {
 		t.Errorf("Log(e) = %v, want 1.0", result.ToFloat())
 	}
 }
 
 func TestLogWithBase(t *testing.T) {
-	result := Log(types.NewFloat(100.0), types.NewFloat(10.0))
+	result, _ := Log(types.NewFloat(100.0), types.NewFloat(10.0))
 	if math.Abs(result.ToFloat()-2.0) > 0.0001 {
 		t.Errorf("Log(100, 10) = %v, want 2.0", result.ToFloat())
 	}
@@ -343,7 +445,7 @@ func TestRandWithLimits(t *testing.T) {
 }
 
 func TestMtRand(t *testing.T) {
-	result := MtRand()
+	result, _ := MtRand()
 	if result.Type() != types.TypeInt {
 		t.Errorf("MtRand() should return int, got %v", result.Type())
 	}
@@ -352,7 +454,7 @@ func TestMtRand(t *testing.T) {
 func TestRandomInt(t *testing.T) {
 	min := types.NewInt(5)
 	max := types.NewInt(15)
-	result := RandomInt(min, max)
+	result, _ := RandomInt(min, max)
 
 	val := result.ToInt()
 	if val < 5 || val > 15 {
@@ -381,6 +483,12 @@ func TestNumberFormat(t *testing.T) {
 		{types.NewFloat(1234.56), []*types.Value{types.NewInt(2)}, "1,234.56"},
 		{types.NewFloat(1234567.891), []*types.Value{types.NewInt(2)}, "1,234,567.89"},
 		{types.NewFloat(1234.56), []*types.Value{types.NewInt(2), types.NewString(","), types.NewString(".")}, "1.234,56"},
+		{types.NewFloat(1.005), []*types.Value{types.NewInt(2)}, "1.01"},
+		{types.NewFloat(-0.4), []*types.Value{}, "0"},
+		{types.NewFloat(-1234.5), []*types.Value{}, "-1,235"},
+		{types.NewInt(1234567), []*types.Value{types.NewInt(-3)}, "1,235,000"},
+		{types.NewFloat(51), []*types.Value{types.NewInt(-2)}, "100"},
+		{types.NewInt(math.MaxInt64), []*types.Value{types.NewInt(1)}, "9,223,372,036,854,775,807.0"},
 	}
 
 	for _, tt := range tests {
@@ -458,16 +566,31 @@ func TestFmod(t *testing.T) {
 }
 
 func TestIntdiv(t *testing.T) {
-	result := Intdiv(types.NewInt(10), types.NewInt(3))
+	result, _ := Intdiv(types.NewInt(10), types.NewInt(3))
 	if result.ToInt() != 3 {
 		t.Errorf("Intdiv(10, 3) = %v, want 3", result.ToInt())
 	}
 }
 
 func TestIntdivByZero(t *testing.T) {
-	result := Intdiv(types.NewInt(10), types.NewInt(0))
-	if result.Type() != types.TypeNull {
-		t.Errorf("Intdiv(10, 0) should return NULL, got %v", result.Type())
+	_, err := Intdiv(types.NewInt(10), types.NewInt(0))
+	e, ok := err.(*Error)
+	if !ok || e.Class != "DivisionByZeroError" || e.Message != "Division by zero" {
+		t.Errorf("Intdiv(10, 0) error = %v, want DivisionByZeroError", err)
+	}
+}
+
+func TestIntdivMinByMinusOne(t *testing.T) {
+	_, err := Intdiv(types.NewInt(math.MinInt64), types.NewInt(-1))
+	if e, ok := err.(*Error); !ok || e.Class != "ArithmeticError" {
+		t.Errorf("Intdiv(PHP_INT_MIN, -1) error = %v, want ArithmeticError", err)
+	}
+}
+
+func TestIntdivTruncates(t *testing.T) {
+	result, _ := Intdiv(types.NewInt(-7), types.NewInt(2))
+	if result.ToInt() != -3 {
+		t.Errorf("Intdiv(-7, 2) = %v, want -3", result.ToInt())
 	}
 }
 
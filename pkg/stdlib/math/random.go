@@ -0,0 +1,233 @@
+package math
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Random Number Generation
+// ============================================================================
+
+// mt19937 is PHP's Mersenne Twister, so that mt_srand() seeds produce the
+// same sequences as in PHP
+type mt19937 struct {
+	state  [mtN]uint32
+	next   int
+	left   int
+	seeded bool
+	mode   int
+}
+
+const (
+	mtN = 624
+	mtM = 397
+)
+
+// mt is the generator shared by rand() and mt_rand() (locked, since
+// parallel tasks share it)
+var (
+	mtLock sync.Mutex
+	mt     mt19937
+)
+
+// twist mixes state words; MT_RAND_PHP keeps the incorrect low bit of
+// PHP before 7.1
+func (g *mt19937) twist(m, u, v uint32) uint32 {
+	mixed := (u & 0x80000000) | (v & 0x7FFFFFFF)
+	low := v & 1
+	if g.mode == MT_RAND_PHP {
+		low = u & 1
+	}
+	return m ^ (mixed >> 1) ^ (uint32(-int32(low)) & 0x9908b0df)
+}
+
+// seed initializes the state from a seed and reloads it
+func (g *mt19937) seed(seed uint32, mode int) {
+	g.state[0] = seed
+	for i := 1; i < mtN; i++ {
+		g.state[i] = 1812433253*(g.state[i-1]^(g.state[i-1]>>30)) + uint32(i)
+	}
+	g.mode = mode
+	g.seeded = true
+	g.reload()
+}
+
+// reload generates the next block of the state
+func (g *mt19937) reload() {
+	s := &g.state
+	i := 0
+	for ; i < mtN-mtM; i++ {
+		s[i] = g.twist(s[i+mtM], s[i], s[i+1])
+	}
+	for ; i < mtN-1; i++ {
+		s[i] = g.twist(s[i+mtM-mtN], s[i], s[i+1])
+	}
+	s[mtN-1] = g.twist(s[mtM-1], s[mtN-1], s[0])
+	g.left = mtN
+	g.next = 0
+}
+
+// uint32 returns the next tempered 32-bit output, seeding from a random
+// source on first use
+func (g *mt19937) uint32() uint32 {
+	if !g.seeded {
+		var b [4]byte
+		rand.Read(b[:])
+		g.seed(binary.LittleEndian.Uint32(b[:]), MT_RAND_MT19937)
+	}
+	if g.left == 0 {
+		g.reload()
+	}
+	g.left--
+	s := g.state[g.next]
+	g.next++
+	s ^= s >> 11
+	s ^= (s << 7) & 0x9d2c5680
+	s ^= (s << 15) & 0xefc60000
+	return s ^ (s >> 18)
+}
+
+// rangeValue returns a uniformly distributed number in [min, max]
+func (g *mt19937) rangeValue(min, max int64) int64 {
+	if g.mode == MT_RAND_PHP {
+		// Legacy scaling of PHP before 7.1, kept for MT_RAND_PHP
+		n := int64(g.uint32() >> 1)
+		return min + int64(float64(max-min+1)*(float64(n)/(float64(math.MaxInt32)+1)))
+	}
+
+	umax := uint64(max) - uint64(min)
+	if umax <= math.MaxUint32 {
+		return min + int64(g.range32(uint32(umax)))
+	}
+	return int64(uint64(min) + g.range64(umax))
+}
+
+func (g *mt19937) range32(umax uint32) uint32 {
+	result := g.uint32()
+	if umax == math.MaxUint32 {
+		return result
+	}
+	umax++
+	if umax&(umax-1) != 0 {
+		limit := math.MaxUint32 - (math.MaxUint32 % umax) - 1
+		for result > limit {
+			result = g.uint32()
+		}
+	}
+	return result % umax
+}
+
+func (g *mt19937) range64(umax uint64) uint64 {
+	result := uint64(g.uint32())<<32 | uint64(g.uint32())
+	if umax == math.MaxUint64 {
+		return result
+	}
+	umax++
+	if umax&(umax-1) != 0 {
+		limit := math.MaxUint64 - (math.MaxUint64 % umax) - 1
+		for result > limit {
+			result = uint64(g.uint32())<<32 | uint64(g.uint32())
+		}
+	}
+	return result % umax
+}
+
+// MtSrand seeds the Mersenne Twister generator
+// mt_srand(int $seed = 0, int $mode = MT_RAND_MT19937): void
+func MtSrand(args ...*types.Value) *types.Value {
+	mtLock.Lock()
+	defer mtLock.Unlock()
+
+	mode := MT_RAND_MT19937
+	if len(args) > 1 && args[1].ToInt() == MT_RAND_PHP {
+		mode = MT_RAND_PHP
+	}
+	if len(args) == 0 || args[0].IsNull() {
+		var b [4]byte
+		rand.Read(b[:])
+		mt.seed(binary.LittleEndian.Uint32(b[:]), mode)
+	} else {
+		mt.seed(uint32(args[0].ToInt()), mode)
+	}
+	return types.NewNull()
+}
+
+// Rand generates a random integer; an alias of mt_rand() that also
+// accepts a max lower than min
+// rand(int $min = 0, int $max = getrandmax()): int
+func Rand(limits ...*types.Value) *types.Value {
+	if len(limits) >= 2 && limits[0].ToInt() > limits[1].ToInt() {
+		limits = []*types.Value{limits[1], limits[0]}
+	}
+	result, _ := MtRand(limits...)
+	return result
+}
+
+// MtRand generates a random number with the Mersenne Twister
+// mt_rand(int $min = 0, int $max = mt_getrandmax()): int
+func MtRand(limits ...*types.Value) (*types.Value, error) {
+	mtLock.Lock()
+	defer mtLock.Unlock()
+
+	if len(limits) < 2 {
+		return types.NewInt(int64(mt.uint32() >> 1)), nil
+	}
+	min, max := limits[0].ToInt(), limits[1].ToInt()
+	if max < min {
+		return nil, &Error{Class: "ValueError", Message: "mt_rand(): Argument #2 ($max) must be greater than or equal to argument #1 ($min)"}
+	}
+	return types.NewInt(mt.rangeValue(min, max)), nil
+}
+
+// RandRange returns a number in [min, max] from the generator mt_srand()
+// seeds, as shuffle(), array_rand() and str_shuffle() use
+func RandRange(min, max int64) int64 {
+	mtLock.Lock()
+	defer mtLock.Unlock()
+	return mt.rangeValue(min, max)
+}
+
+// RandomInt generates a cryptographically secure random integer
+// random_int(int $min, int $max): int
+func RandomInt(min, max *types.Value) (*types.Value, error) {
+	lo, hi := min.ToInt(), max.ToInt()
+	if lo > hi {
+		return nil, &Error{Class: "ValueError", Message: "random_int(): Argument #1 ($min) must be less than or equal to argument #2 ($max)"}
+	}
+
+	umax := uint64(hi) - uint64(lo)
+	var b [8]byte
+	for {
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, &Error{Class: "Exception", Message: fmt.Sprintf("Cannot gather sufficient random data: %v", err)}
+		}
+		n := binary.LittleEndian.Uint64(b[:])
+		if umax == math.MaxUint64 {
+			return types.NewInt(int64(n)), nil
+		}
+		// Reject the values below the bias threshold so that every value
+		// of the range is equally likely
+		span := umax + 1
+		if n >= -span%span {
+			return types.NewInt(int64(uint64(lo) + n%span)), nil
+		}
+	}
+}
+
+// GetRandMax returns the maximum random number
+// getrandmax(): int
+func GetRandMax() *types.Value {
+	return types.NewInt(math.MaxInt32)
+}
+
+// MtGetRandMax returns the maximum random number for mt_rand
+// mt_getrandmax(): int
+func MtGetRandMax() *types.Value {
+	return types.NewInt(math.MaxInt32)
+}
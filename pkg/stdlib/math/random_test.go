@@ -0,0 +1,58 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestMtSrandSequence(t *testing.T) {
+	// Sequences must match PHP for the same seed
+	MtSrand(types.NewInt(1))
+	first, _ := MtRand()
+	if first.ToInt() != 895547922 {
+		t.Errorf("mt_rand() after mt_srand(1) = %d, want 895547922", first.ToInt())
+	}
+	second, _ := MtRand()
+	if second.ToInt() != 2141438069 {
+		t.Errorf("second mt_rand() after mt_srand(1) = %d, want 2141438069", second.ToInt())
+	}
+
+	MtSrand(types.NewInt(1))
+	again, _ := MtRand()
+	if again.ToInt() != first.ToInt() {
+		t.Errorf("reseeding gave %d, want %d", again.ToInt(), first.ToInt())
+	}
+}
+
+func TestMtRandRange(t *testing.T) {
+	MtSrand(types.NewInt(42))
+	for i := 0; i < 1000; i++ {
+		result, err := MtRand(types.NewInt(-3), types.NewInt(3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := result.ToInt(); n < -3 || n > 3 {
+			t.Fatalf("MtRand(-3, 3) = %d, out of range", n)
+		}
+	}
+}
+
+func TestMtRandInvalidRange(t *testing.T) {
+	_, err := MtRand(types.NewInt(10), types.NewInt(1))
+	if e, ok := err.(*Error); !ok || e.Class != "ValueError" {
+		t.Errorf("MtRand(10, 1) error = %v, want ValueError", err)
+	}
+
+	// rand() swaps the limits instead
+	if n := Rand(types.NewInt(10), types.NewInt(1)).ToInt(); n < 1 || n > 10 {
+		t.Errorf("Rand(10, 1) = %d, out of range", n)
+	}
+}
+
+func TestRandomIntInvalidRange(t *testing.T) {
+	_, err := RandomInt(types.NewInt(5), types.NewInt(1))
+	if e, ok := err.(*Error); !ok || e.Class != "ValueError" {
+		t.Errorf("RandomInt(5, 1) error = %v, want ValueError", err)
+	}
+}
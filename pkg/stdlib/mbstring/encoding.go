@@ -0,0 +1,221 @@
+package mbstring
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Character Encodings
+// ============================================================================
+
+// substituteCharacter replaces characters that cannot be represented in
+// the target encoding (mbstring.substitute_character defaults to '?')
+const substituteCharacter = '?'
+
+// Encoding is a character encoding mbstring functions operate in
+type Encoding struct {
+	Name    string   // Canonical name, as reported by mb_internal_encoding()
+	Aliases []string // Other accepted names (matched case-insensitively)
+
+	// next returns the character at the start of s, its length in bytes
+	// and whether it is a valid character of the encoding
+	next func(s string) (rune, int, bool)
+	// encode appends r to b; it returns false if r is not representable
+	encode func(b []byte, r rune) ([]byte, bool)
+}
+
+var (
+	UTF8 = &Encoding{
+		Name:    "UTF-8",
+		Aliases: []string{"utf8"},
+		next: func(s string) (rune, int, bool) {
+			r, size := utf8.DecodeRuneInString(s)
+			return r, size, r != utf8.RuneError || size > 1
+		},
+		encode: func(b []byte, r rune) ([]byte, bool) {
+			if !utf8.ValidRune(r) {
+				return b, false
+			}
+			return utf8.AppendRune(b, r), true
+		},
+	}
+
+	Latin1 = &Encoding{
+		Name:    "ISO-8859-1",
+		Aliases: []string{"ISO8859-1", "latin1"},
+		next: func(s string) (rune, int, bool) {
+			return rune(s[0]), 1, true
+		},
+		encode: func(b []byte, r rune) ([]byte, bool) {
+			if r > 0xFF {
+				return b, false
+			}
+			return append(b, byte(r)), true
+		},
+	}
+
+	ASCII = &Encoding{
+		Name:    "ASCII",
+		Aliases: []string{"US-ASCII", "ANSI_X3.4-1968", "ISO646-US"},
+		next: func(s string) (rune, int, bool) {
+			return rune(s[0]), 1, s[0] < 0x80
+		},
+		encode: func(b []byte, r rune) ([]byte, bool) {
+			if r >= 0x80 {
+				return b, false
+			}
+			return append(b, byte(r)), true
+		},
+	}
+)
+
+// encodings lists the supported encodings in mb_list_encodings() order
+var encodings = []*Encoding{ASCII, Latin1, UTF8}
+
+// internalEncoding is the default encoding of the mbstring functions
+// (atomic, since parallel tasks share it)
+var internalEncoding atomic.Pointer[Encoding]
+
+func init() {
+	internalEncoding.Store(UTF8)
+}
+
+// LookupEncoding returns the encoding with the given name or alias
+func LookupEncoding(name string) (*Encoding, bool) {
+	for _, enc := range encodings {
+		if strings.EqualFold(enc.Name, name) {
+			return enc, true
+		}
+		for _, alias := range enc.Aliases {
+			if strings.EqualFold(alias, name) {
+				return enc, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Encodings returns the supported encodings
+func Encodings() []*Encoding {
+	return encodings
+}
+
+// InternalEncoding returns the current internal encoding
+func InternalEncoding() *Encoding {
+	return internalEncoding.Load()
+}
+
+// SetInternalEncoding changes the internal encoding
+func SetInternalEncoding(enc *Encoding) {
+	internalEncoding.Store(enc)
+}
+
+// chars splits s into its characters. Every byte of an invalid sequence
+// is a character of its own.
+func (enc *Encoding) chars(s string) []string {
+	chars := make([]string, 0, len(s))
+	for len(s) > 0 {
+		_, size, _ := enc.next(s)
+		chars = append(chars, s[:size])
+		s = s[size:]
+	}
+	return chars
+}
+
+// Valid reports whether s is a valid string in the encoding
+func (enc *Encoding) Valid(s string) bool {
+	for len(s) > 0 {
+		_, size, ok := enc.next(s)
+		if !ok {
+			return false
+		}
+		s = s[size:]
+	}
+	return true
+}
+
+// mapString decodes s, replaces every character with the result of fn
+// and encodes it again. Invalid characters and mappings the encoding
+// cannot represent become the substitute character.
+func (enc *Encoding) mapString(s string, fn func(r rune) []rune) string {
+	b := make([]byte, 0, len(s))
+	for len(s) > 0 {
+		r, size, ok := enc.next(s)
+		s = s[size:]
+		if !ok {
+			b = append(b, substituteCharacter)
+			continue
+		}
+
+		start, valid := len(b), true
+		for _, m := range fn(r) {
+			if b, ok = enc.encode(b, m); !ok {
+				valid = false
+				break
+			}
+		}
+		if !valid {
+			b = append(b[:start], substituteCharacter)
+		}
+	}
+	return string(b)
+}
+
+// Convert converts s from one encoding to another. Characters that are
+// invalid in from or that to cannot represent become the substitute
+// character.
+func Convert(s string, to, from *Encoding) string {
+	if to == from && from.Valid(s) {
+		return s
+	}
+	b := make([]byte, 0, len(s))
+	for len(s) > 0 {
+		r, size, ok := from.next(s)
+		s = s[size:]
+		if ok {
+			if b, ok = to.encode(b, r); ok {
+				continue
+			}
+		}
+		b, _ = to.encode(b, substituteCharacter)
+	}
+	return string(b)
+}
+
+// ============================================================================
+// Encoding Arguments
+// ============================================================================
+
+// Error is an mbstring argument error, thrown as a ValueError. The message
+// does not include the function name.
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func valueError(format string, args ...interface{}) error {
+	return &Error{Message: fmt.Sprintf(format, args...)}
+}
+
+// encodingArg resolves the optional ?string $encoding argument args[index]
+// (parameter number position): a missing or null argument selects the
+// internal encoding
+func encodingArg(args []*types.Value, index, position int) (*Encoding, error) {
+	if index >= len(args) || args[index] == nil || args[index].IsNull() {
+		return InternalEncoding(), nil
+	}
+	name := args[index].ToString()
+	enc, ok := LookupEncoding(name)
+	if !ok {
+		return nil, valueError("Argument #%d ($encoding) must be a valid encoding, \"%s\" given", position, name)
+	}
+	return enc, nil
+}
@@ -0,0 +1,477 @@
+package mbstring
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Case Conversion Modes
+// ============================================================================
+
+const (
+	MB_CASE_UPPER        = 0
+	MB_CASE_LOWER        = 1
+	MB_CASE_TITLE        = 2
+	MB_CASE_FOLD         = 3
+	MB_CASE_UPPER_SIMPLE = 4
+	MB_CASE_LOWER_SIMPLE = 5
+	MB_CASE_TITLE_SIMPLE = 6
+	MB_CASE_FOLD_SIMPLE  = 7
+)
+
+// Constants returns the constants defined by the mbstring extension
+func Constants() map[string]*types.Value {
+	return map[string]*types.Value{
+		"MB_CASE_UPPER":        types.NewInt(MB_CASE_UPPER),
+		"MB_CASE_LOWER":        types.NewInt(MB_CASE_LOWER),
+		"MB_CASE_TITLE":        types.NewInt(MB_CASE_TITLE),
+		"MB_CASE_FOLD":         types.NewInt(MB_CASE_FOLD),
+		"MB_CASE_UPPER_SIMPLE": types.NewInt(MB_CASE_UPPER_SIMPLE),
+		"MB_CASE_LOWER_SIMPLE": types.NewInt(MB_CASE_LOWER_SIMPLE),
+		"MB_CASE_TITLE_SIMPLE": types.NewInt(MB_CASE_TITLE_SIMPLE),
+		"MB_CASE_FOLD_SIMPLE":  types.NewInt(MB_CASE_FOLD_SIMPLE),
+	}
+}
+
+// arg returns the optional argument args[i], or nil when it is missing
+func arg(args []*types.Value, i int) *types.Value {
+	if i < len(args) && args[i] != nil {
+		return args[i]
+	}
+	return nil
+}
+
+// ============================================================================
+// Length and Substrings
+// ============================================================================
+
+// MbStrlen returns the length of a string in characters
+// mb_strlen(string $string, ?string $encoding = null): int
+func MbStrlen(str *types.Value, args ...*types.Value) (*types.Value, error) {
+	enc, err := encodingArg(args, 0, 2)
+	if err != nil {
+		return nil, err
+	}
+	s := str.ToString()
+	if enc != UTF8 {
+		return types.NewInt(int64(len(enc.chars(s)))), nil
+	}
+	n := 0
+	for range s {
+		n++
+	}
+	return types.NewInt(int64(n)), nil
+}
+
+// MbSubstr returns the part of a string given in characters; negative
+// values count from the end as with substr()
+// mb_substr(string $string, int $start, ?int $length = null, ?string $encoding = null): string
+func MbSubstr(str, start *types.Value, args ...*types.Value) (*types.Value, error) {
+	enc, err := encodingArg(args, 1, 4)
+	if err != nil {
+		return nil, err
+	}
+	chars := enc.chars(str.ToString())
+	from, to := substrRange(len(chars), int(start.ToInt()), arg(args, 0))
+	return types.NewString(strings.Join(chars[from:to], "")), nil
+}
+
+// substrRange resolves the start and length of a substring of n
+// characters to the range [from, to)
+func substrRange(n, start int, length *types.Value) (int, int) {
+	if start < 0 {
+		start = max(n+start, 0)
+	}
+	if start > n {
+		return n, n
+	}
+	end := n
+	if length != nil && !length.IsNull() {
+		if l := int(length.ToInt()); l < 0 {
+			end = max(n+l, start)
+		} else {
+			end = min(start+l, n)
+		}
+	}
+	return start, end
+}
+
+// MbStrSplit splits a string into chunks of length characters
+// mb_str_split(string $string, int $length = 1, ?string $encoding = null): array
+func MbStrSplit(str *types.Value, args ...*types.Value) (*types.Value, error) {
+	length := 1
+	if l := arg(args, 0); l != nil {
+		length = int(l.ToInt())
+	}
+	if length < 1 {
+		return nil, valueError("Argument #2 ($length) must be greater than 0")
+	}
+	enc, err := encodingArg(args, 1, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	chars := enc.chars(str.ToString())
+	result := types.NewEmptyArray()
+	for i := 0; i < len(chars); i += length {
+		result.Append(types.NewString(strings.Join(chars[i:min(i+length, len(chars))], "")))
+	}
+	return types.NewArray(result), nil
+}
+
+// ============================================================================
+// Searching
+// ============================================================================
+
+// MbStrpos finds the character position of the first occurrence of needle
+// mb_strpos(string $haystack, string $needle, int $offset = 0, ?string $encoding = null): int|false
+func MbStrpos(haystack, needle *types.Value, args ...*types.Value) (*types.Value, error) {
+	return search(haystack, needle, args, false, false)
+}
+
+// MbStripos is a case-insensitive MbStrpos
+// mb_stripos(string $haystack, string $needle, int $offset = 0, ?string $encoding = null): int|false
+func MbStripos(haystack, needle *types.Value, args ...*types.Value) (*types.Value, error) {
+	return search(haystack, needle, args, false, true)
+}
+
+// MbStrrpos finds the character position of the last occurrence of needle
+// mb_strrpos(string $haystack, string $needle, int $offset = 0, ?string $encoding = null): int|false
+func MbStrrpos(haystack, needle *types.Value, args ...*types.Value) (*types.Value, error) {
+	return search(haystack, needle, args, true, false)
+}
+
+// search implements the mb_strpos family. A non-negative offset skips
+// characters at the start; a negative one starts the search that many
+// characters from the end (for mb_strrpos, the needle may not start after
+// that point).
+func search(haystack, needle *types.Value, args []*types.Value, last, fold bool) (*types.Value, error) {
+	offset := 0
+	if o := arg(args, 0); o != nil {
+		offset = int(o.ToInt())
+	}
+	enc, err := encodingArg(args, 1, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	h := enc.chars(haystack.ToString())
+	n := enc.chars(needle.ToString())
+	if offset > len(h) || offset < -len(h) {
+		return nil, valueError("Argument #3 ($offset) must be contained in argument #1 ($haystack)")
+	}
+	if fold {
+		foldChars(enc, h)
+		foldChars(enc, n)
+	}
+
+	from, to := offset, len(h)
+	if offset < 0 {
+		from = len(h) + offset
+		if last {
+			from, to = 0, min(len(h), from+len(n))
+		}
+	}
+
+	if last {
+		for i := to - len(n); i >= from; i-- {
+			if matchChars(h[i:], n) {
+				return types.NewInt(int64(i)), nil
+			}
+		}
+	} else {
+		for i := from; i+len(n) <= to; i++ {
+			if matchChars(h[i:], n) {
+				return types.NewInt(int64(i)), nil
+			}
+		}
+	}
+	return types.NewBool(false), nil
+}
+
+// matchChars reports whether h starts with the characters of n
+func matchChars(h, n []string) bool {
+	for i := range n {
+		if h[i] != n[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// foldChars case-folds every valid character in place (simple folding,
+// which keeps character positions)
+func foldChars(enc *Encoding, chars []string) {
+	for i, c := range chars {
+		if !enc.Valid(c) {
+			continue
+		}
+		chars[i] = enc.mapString(c, func(r rune) []rune { return []rune{simpleFold(r)} })
+	}
+}
+
+// ============================================================================
+// Case Conversion
+// ============================================================================
+
+// Full case mappings that expand to several characters. Characters not
+// listed here map to their simple (one-to-one) mapping.
+var (
+	upperSpecial = map[rune][]rune{
+		'ß': []rune("SS"), 'ŉ': []rune("ʼN"), 'ǰ': []rune("J\u030C"),
+		'ﬀ': []rune("FF"), 'ﬁ': []rune("FI"), 'ﬂ': []rune("FL"),
+		'ﬃ': []rune("FFI"), 'ﬄ': []rune("FFL"), 'ﬅ': []rune("ST"), 'ﬆ': []rune("ST"),
+	}
+	titleSpecial = map[rune][]rune{
+		'ß': []rune("Ss"), 'ŉ': []rune("ʼN"), 'ǰ': []rune("J\u030C"),
+		'ﬀ': []rune("Ff"), 'ﬁ': []rune("Fi"), 'ﬂ': []rune("Fl"),
+		'ﬃ': []rune("Ffi"), 'ﬄ': []rune("Ffl"), 'ﬅ': []rune("St"), 'ﬆ': []rune("St"),
+	}
+	lowerSpecial = map[rune][]rune{
+		'İ': []rune("i\u0307"),
+	}
+	foldSpecial = map[rune][]rune{
+		'ß': []rune("ss"), 'ẞ': []rune("ss"), 'ŉ': []rune("ʼn"), 'ǰ': []rune("j\u030C"), 'İ': []rune("i\u0307"),
+		'ﬀ': []rune("ff"), 'ﬁ': []rune("fi"), 'ﬂ': []rune("fl"),
+		'ﬃ': []rune("ffi"), 'ﬄ': []rune("ffl"), 'ﬅ': []rune("st"), 'ﬆ': []rune("st"),
+	}
+)
+
+// simpleFold returns the simple case folding of r
+func simpleFold(r rune) rune {
+	return unicode.ToLower(unicode.ToUpper(r))
+}
+
+// caseMapping returns the mapping of a case conversion mode
+func caseMapping(special map[rune][]rune, simple func(rune) rune) func(rune) []rune {
+	return func(r rune) []rune {
+		if m, ok := special[r]; ok {
+			return m
+		}
+		return []rune{simple(r)}
+	}
+}
+
+// isCased reports whether r has case (Unicode's Cased property)
+func isCased(r rune) bool {
+	return unicode.IsUpper(r) || unicode.IsLower(r) || unicode.IsTitle(r) ||
+		unicode.Is(unicode.Other_Lowercase, r) || unicode.Is(unicode.Other_Uppercase, r)
+}
+
+// isCaseIgnorable reports whether r is skipped when finding word
+// boundaries for title case (Unicode's Case_Ignorable property)
+func isCaseIgnorable(r rune) bool {
+	switch r {
+	case '\'', '.', ':', '·', '‘', '’', '․', '﹒', '＇', '．':
+		return true
+	}
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf, unicode.Lm, unicode.Sk)
+}
+
+// MbConvertCase converts the case of a string according to an MB_CASE_*
+// mode. The full modes apply mappings that change the length ("ß" to
+// "SS"); title case capitalizes the first cased character of every word.
+// mb_convert_case(string $string, int $mode, ?string $encoding = null): string
+func MbConvertCase(str, mode *types.Value, args ...*types.Value) (*types.Value, error) {
+	enc, err := encodingArg(args, 0, 3)
+	if err != nil {
+		return nil, err
+	}
+	s := str.ToString()
+
+	var fn func(rune) []rune
+	switch mode.ToInt() {
+	case MB_CASE_UPPER:
+		fn = caseMapping(upperSpecial, unicode.ToUpper)
+	case MB_CASE_LOWER:
+		fn = caseMapping(lowerSpecial, unicode.ToLower)
+	case MB_CASE_FOLD:
+		fn = caseMapping(foldSpecial, simpleFold)
+	case MB_CASE_UPPER_SIMPLE:
+		fn = caseMapping(nil, unicode.ToUpper)
+	case MB_CASE_LOWER_SIMPLE:
+		fn = caseMapping(nil, unicode.ToLower)
+	case MB_CASE_FOLD_SIMPLE:
+		fn = caseMapping(nil, simpleFold)
+	case MB_CASE_TITLE:
+		fn = titleCase(caseMapping(titleSpecial, unicode.ToTitle), caseMapping(lowerSpecial, unicode.ToLower))
+	case MB_CASE_TITLE_SIMPLE:
+		fn = titleCase(caseMapping(nil, unicode.ToTitle), caseMapping(nil, unicode.ToLower))
+	default:
+		return nil, valueError("Argument #2 ($mode) must be one of the MB_CASE_* constants")
+	}
+	return types.NewString(enc.mapString(s, fn)), nil
+}
+
+// titleCase returns a mapping that title-cases the first cased character
+// of each word and lowercases the rest
+func titleCase(title, lower func(rune) []rune) func(rune) []rune {
+	inWord := false
+	return func(r rune) []rune {
+		wasInWord := inWord
+		if !isCaseIgnorable(r) {
+			inWord = isCased(r)
+		}
+		if wasInWord {
+			return lower(r)
+		}
+		return title(r)
+	}
+}
+
+// MbStrtoupper makes a string uppercase
+// mb_strtoupper(string $string, ?string $encoding = null): string
+func MbStrtoupper(str *types.Value, args ...*types.Value) (*types.Value, error) {
+	return MbConvertCase(str, types.NewInt(MB_CASE_UPPER), args...)
+}
+
+// MbStrtolower makes a string lowercase
+// mb_strtolower(string $string, ?string $encoding = null): string
+func MbStrtolower(str *types.Value, args ...*types.Value) (*types.Value, error) {
+	return MbConvertCase(str, types.NewInt(MB_CASE_LOWER), args...)
+}
+
+// ============================================================================
+// Encoding Conversion and Validation
+// ============================================================================
+
+// MbConvertEncoding converts a string, or the keys and values of an array,
+// to another encoding. $from_encoding may list several encodings (as an
+// array or comma-separated); the first one the input is valid in is used,
+// falling back to the first listed.
+// mb_convert_encoding(array|string $string, string $to_encoding, array|string|null $from_encoding = null): array|string
+func MbConvertEncoding(value, toEncoding *types.Value, args ...*types.Value) (*types.Value, error) {
+	name := toEncoding.ToString()
+	to, ok := LookupEncoding(name)
+	if !ok {
+		return nil, valueError("Argument #2 ($to_encoding) must be a valid encoding, \"%s\" given", name)
+	}
+	candidates, err := fromEncodings(arg(args, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	convert := func(s string) string {
+		from := candidates[0]
+		for _, enc := range candidates {
+			if enc.Valid(s) {
+				from = enc
+				break
+			}
+		}
+		return Convert(s, to, from)
+	}
+	return convertValue(value, convert), nil
+}
+
+// fromEncodings resolves the $from_encoding argument of mb_convert_encoding
+func fromEncodings(from *types.Value) ([]*Encoding, error) {
+	if from == nil || from.IsNull() {
+		return []*Encoding{InternalEncoding()}, nil
+	}
+
+	var names []string
+	if from.IsArray() {
+		from.ToArray().Each(func(_, v *types.Value) bool {
+			names = append(names, v.ToString())
+			return true
+		})
+	} else {
+		names = strings.Split(from.ToString(), ",")
+	}
+
+	var candidates []*Encoding
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" && len(names) == 1 {
+			break
+		}
+		enc, ok := LookupEncoding(name)
+		if !ok {
+			if len(names) == 1 {
+				return nil, valueError("Argument #3 ($from_encoding) must be a valid encoding, \"%s\" given", name)
+			}
+			return nil, valueError("Argument #3 ($from_encoding) contains invalid encoding \"%s\"", name)
+		}
+		candidates = append(candidates, enc)
+	}
+	if len(candidates) == 0 {
+		return nil, valueError("Argument #3 ($from_encoding) must specify at least one encoding")
+	}
+	return candidates, nil
+}
+
+// convertValue applies convert to a string, or recursively to the string
+// keys and values of an array
+func convertValue(value *types.Value, convert func(string) string) *types.Value {
+	if !value.IsArray() {
+		return types.NewString(convert(value.ToString()))
+	}
+	result := types.NewEmptyArray()
+	value.ToArray().Each(func(k, v *types.Value) bool {
+		if k.Type() == types.TypeString {
+			k = types.NewString(convert(k.ToString()))
+		}
+		if v.IsArray() || v.Type() == types.TypeString {
+			v = convertValue(v, convert)
+		}
+		result.Set(k, v)
+		return true
+	})
+	return types.NewArray(result)
+}
+
+// MbCheckEncoding checks that a string, or the keys and values of an
+// array, are valid in an encoding
+// mb_check_encoding(array|string|null $value = null, ?string $encoding = null): bool
+func MbCheckEncoding(value *types.Value, args ...*types.Value) (*types.Value, error) {
+	enc, err := encodingArg(args, 0, 2)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil || value.IsNull() {
+		return types.NewBool(true), nil
+	}
+	return types.NewBool(checkValue(value, enc)), nil
+}
+
+func checkValue(value *types.Value, enc *Encoding) bool {
+	if !value.IsArray() {
+		return enc.Valid(value.ToString())
+	}
+	valid := true
+	value.ToArray().Each(func(k, v *types.Value) bool {
+		valid = checkValue(k, enc) && checkValue(v, enc)
+		return valid
+	})
+	return valid
+}
+
+// MbInternalEncoding returns the internal encoding, or sets it and
+// returns true
+// mb_internal_encoding(?string $encoding = null): string|bool
+func MbInternalEncoding(args ...*types.Value) (*types.Value, error) {
+	encoding := arg(args, 0)
+	if encoding == nil || encoding.IsNull() {
+		return types.NewString(InternalEncoding().Name), nil
+	}
+	enc, err := encodingArg(args, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	SetInternalEncoding(enc)
+	return types.NewBool(true), nil
+}
+
+// MbListEncodings returns the names of the supported encodings
+// mb_list_encodings(): array
+func MbListEncodings() *types.Value {
+	result := types.NewEmptyArray()
+	for _, enc := range encodings {
+		result.Append(types.NewString(enc.Name))
+	}
+	return types.NewArray(result)
+}
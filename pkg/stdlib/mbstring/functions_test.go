@@ -0,0 +1,351 @@
+package mbstring
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func str(s string) *types.Value {
+	return types.NewString(s)
+}
+
+func get(arr *types.Array, key *types.Value) *types.Value {
+	v, _ := arr.Get(key)
+	return v
+}
+
+func stringValues(values ...string) []*types.Value {
+	result := make([]*types.Value, len(values))
+	for i, v := range values {
+		result[i] = types.NewString(v)
+	}
+	return result
+}
+
+// ============================================================================
+// Length and Substring Tests
+// ============================================================================
+
+func TestMbStrlen(t *testing.T) {
+	tests := []struct {
+		input    string
+		encoding *types.Value
+		expected int64
+	}{
+		{"hello", nil, 5},
+		{"héllo", nil, 5},
+		{"日本語", nil, 3},
+		{"😀!", nil, 2},
+		{"", nil, 0},
+		{"h\xffi", nil, 3},
+		{"日本語", str("ISO-8859-1"), 9},
+		{"héllo", str("ascii"), 6},
+	}
+
+	for _, tt := range tests {
+		result, err := MbStrlen(str(tt.input), tt.encoding)
+		if err != nil {
+			t.Fatalf("MbStrlen(%q): unexpected error: %v", tt.input, err)
+		}
+		if result.ToInt() != tt.expected {
+			t.Errorf("MbStrlen(%q, %v) = %d, want %d", tt.input, tt.encoding, result.ToInt(), tt.expected)
+		}
+	}
+}
+
+func TestMbSubstr(t *testing.T) {
+	tests := []struct {
+		start    int64
+		length   *types.Value
+		expected string
+	}{
+		{0, nil, "Ünïcödé"},
+		{2, nil, "ïcödé"},
+		{1, types.NewInt(3), "nïc"},
+		{-3, nil, "ödé"},
+		{-3, types.NewInt(2), "öd"},
+		{1, types.NewInt(-2), "nïcö"},
+		{5, types.NewInt(-4), ""},
+		{10, nil, ""},
+		{-10, types.NewInt(2), "Ün"},
+		{0, types.NewNull(), "Ünïcödé"},
+	}
+
+	for _, tt := range tests {
+		result, err := MbSubstr(str("Ünïcödé"), types.NewInt(tt.start), tt.length)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.ToString() != tt.expected {
+			t.Errorf("MbSubstr(%d, %v) = %q, want %q", tt.start, tt.length, result.ToString(), tt.expected)
+		}
+	}
+}
+
+func TestMbStrSplit(t *testing.T) {
+	result, err := MbStrSplit(str("añbç日"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"a", "ñ", "b", "ç", "日"}
+	arr := result.ToArray()
+	if arr.Len() != len(expected) {
+		t.Fatalf("Expected %d chunks, got %d", len(expected), arr.Len())
+	}
+	for i, want := range expected {
+		if got := get(arr, types.NewInt(int64(i))).ToString(); got != want {
+			t.Errorf("Chunk %d = %q, want %q", i, got, want)
+		}
+	}
+
+	result, _ = MbStrSplit(str("añbç日"), types.NewInt(2))
+	if arr := result.ToArray(); arr.Len() != 3 || get(arr, types.NewInt(2)).ToString() != "日" {
+		t.Errorf("Expected chunks of two characters, got %v", result)
+	}
+
+	result, _ = MbStrSplit(str(""))
+	if result.ToArray().Len() != 0 {
+		t.Errorf("Expected an empty array for an empty string")
+	}
+
+	if _, err := MbStrSplit(str("abc"), types.NewInt(0)); err == nil || err.Error() != "Argument #2 ($length) must be greater than 0" {
+		t.Errorf("Expected a length error, got %v", err)
+	}
+}
+
+// ============================================================================
+// Search Tests
+// ============================================================================
+
+func TestMbStrpos(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       func(haystack, needle *types.Value, args ...*types.Value) (*types.Value, error)
+		needle   string
+		offset   int64
+		expected interface{}
+	}{
+		{"strpos", MbStrpos, "ö", 0, int64(2)},
+		{"strpos offset", MbStrpos, "ö", 3, int64(7)},
+		{"strpos negative offset", MbStrpos, "ö", -3, int64(7)},
+		{"strpos missing", MbStrpos, "x", 0, false},
+		{"strpos case", MbStrpos, "Ö", 0, false},
+		{"strpos empty needle", MbStrpos, "", 4, int64(4)},
+		{"stripos", MbStripos, "Ö", 0, int64(2)},
+		{"stripos word", MbStripos, "BÖB", 0, int64(6)},
+		{"strrpos", MbStrrpos, "ö", 0, int64(7)},
+		{"strrpos negative offset", MbStrrpos, "ö", -3, int64(2)},
+		{"strrpos negative offset match", MbStrrpos, "öb", -2, int64(7)},
+		{"strrpos negative offset too late", MbStrrpos, "öb", -3, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// "dröhn böb" has ö at character positions 2 and 7
+			result, err := tt.fn(str("dröhn böb"), str(tt.needle), types.NewInt(tt.offset))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			switch want := tt.expected.(type) {
+			case bool:
+				if !result.IsBool() || result.ToBool() != want {
+					t.Errorf("Expected %v, got %v", want, result)
+				}
+			case int64:
+				if !result.IsInt() || result.ToInt() != want {
+					t.Errorf("Expected %d, got %v", want, result)
+				}
+			}
+		})
+	}
+
+	_, err := MbStrpos(str("äbc"), str("b"), types.NewInt(4))
+	if err == nil || err.Error() != "Argument #3 ($offset) must be contained in argument #1 ($haystack)" {
+		t.Errorf("Expected an offset error, got %v", err)
+	}
+}
+
+// ============================================================================
+// Case Conversion Tests
+// ============================================================================
+
+func TestMbConvertCase(t *testing.T) {
+	tests := []struct {
+		input    string
+		mode     int64
+		expected string
+	}{
+		{"straße", MB_CASE_UPPER, "STRASSE"},
+		{"straße", MB_CASE_UPPER_SIMPLE, "STRAßE"},
+		{"ÀÉÎÕÜ", MB_CASE_LOWER, "àéîõü"},
+		{"ΣΊΣΥΦΟΣ", MB_CASE_LOWER, "σίσυφοσ"},
+		{"hello wörld-ünd ßo", MB_CASE_TITLE, "Hello Wörld-Ünd Sso"},
+		{"hello wörld-ünd ßo", MB_CASE_TITLE_SIMPLE, "Hello Wörld-Ünd ßo"},
+		{"o'NEIL mcDONALD", MB_CASE_TITLE, "O'neil Mcdonald"},
+		{"Straße ſ", MB_CASE_FOLD, "strasse s"},
+		{"Straße ſ", MB_CASE_FOLD_SIMPLE, "straße s"},
+		{"ǆemal", MB_CASE_TITLE, "ǅemal"},
+	}
+
+	for _, tt := range tests {
+		result, err := MbConvertCase(str(tt.input), types.NewInt(tt.mode))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.ToString() != tt.expected {
+			t.Errorf("MbConvertCase(%q, %d) = %q, want %q", tt.input, tt.mode, result.ToString(), tt.expected)
+		}
+	}
+
+	if _, err := MbConvertCase(str("a"), types.NewInt(8)); err == nil {
+		t.Errorf("Expected an error for an invalid mode")
+	}
+}
+
+func TestMbStrtoupperStrtolower(t *testing.T) {
+	result, _ := MbStrtoupper(str("äbç"))
+	if result.ToString() != "ÄBÇ" {
+		t.Errorf("MbStrtoupper = %q, want ÄBÇ", result.ToString())
+	}
+	result, _ = MbStrtolower(str("ÄBÇ"))
+	if result.ToString() != "äbç" {
+		t.Errorf("MbStrtolower = %q, want äbç", result.ToString())
+	}
+
+	// In ISO-8859-1 "\xe4" is ä; ÿ has no uppercase form in the encoding
+	result, _ = MbStrtoupper(str("\xe4\xff"), str("ISO-8859-1"))
+	if result.ToString() != "\xc4?" {
+		t.Errorf("MbStrtoupper in ISO-8859-1 = %q, want %q", result.ToString(), "\xc4?")
+	}
+	// Invalid bytes become the substitute character
+	result, _ = MbStrtolower(str("A\xffB"))
+	if result.ToString() != "a?b" {
+		t.Errorf("MbStrtolower with invalid UTF-8 = %q, want a?b", result.ToString())
+	}
+}
+
+// ============================================================================
+// Encoding Tests
+// ============================================================================
+
+func TestMbConvertEncoding(t *testing.T) {
+	tests := []struct {
+		input    string
+		to, from string
+		expected string
+	}{
+		{"café", "ISO-8859-1", "UTF-8", "caf\xe9"},
+		{"caf\xe9", "UTF-8", "latin1", "café"},
+		{"café 日本", "ISO-8859-1", "UTF-8", "caf\xe9 ??"},
+		{"café", "ASCII", "UTF-8", "caf?"},
+		{"caf\xe9", "UTF-8", "ASCII", "caf?"},
+		{"caf\xe9", "UTF-8", "UTF-8, ISO-8859-1", "café"},
+		{"café", "ISO-8859-1", "UTF-8, ISO-8859-1", "caf\xe9"},
+	}
+
+	for _, tt := range tests {
+		result, err := MbConvertEncoding(str(tt.input), str(tt.to), str(tt.from))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.ToString() != tt.expected {
+			t.Errorf("MbConvertEncoding(%q, %s, %s) = %q, want %q", tt.input, tt.to, tt.from, result.ToString(), tt.expected)
+		}
+	}
+
+	arr := types.NewEmptyArray()
+	arr.Set(str("clé"), str("été"))
+	arr.Set(types.NewInt(0), types.NewInt(5))
+	result, err := MbConvertEncoding(types.NewArray(arr), str("ISO-8859-1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	converted := result.ToArray()
+	if v := get(converted, str("cl\xe9")); v == nil || v.ToString() != "\xe9t\xe9" {
+		t.Errorf("Expected array keys and values to be converted, got %v", result)
+	}
+	if v := get(converted, types.NewInt(0)); v == nil || !v.IsInt() {
+		t.Errorf("Expected non-string values to be kept, got %v", v)
+	}
+}
+
+func TestMbConvertEncodingErrors(t *testing.T) {
+	tests := []struct {
+		to       string
+		from     *types.Value
+		expected string
+	}{
+		{"EBCDIC", nil, `Argument #2 ($to_encoding) must be a valid encoding, "EBCDIC" given`},
+		{"UTF-8", str("EBCDIC"), `Argument #3 ($from_encoding) must be a valid encoding, "EBCDIC" given`},
+		{"UTF-8", str("UTF-8,EBCDIC"), `Argument #3 ($from_encoding) contains invalid encoding "EBCDIC"`},
+		{"UTF-8", types.NewArray(types.NewEmptyArray()), "Argument #3 ($from_encoding) must specify at least one encoding"},
+	}
+
+	for _, tt := range tests {
+		_, err := MbConvertEncoding(str("x"), str(tt.to), tt.from)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("Expected %q, got %v", tt.expected, err)
+		}
+	}
+}
+
+func TestMbCheckEncoding(t *testing.T) {
+	tests := []struct {
+		value    *types.Value
+		encoding *types.Value
+		expected bool
+	}{
+		{str("héllo"), nil, true},
+		{str("h\xe9llo"), nil, false},
+		{str("\xc0\xaf"), nil, false},     // overlong encoding
+		{str("\xed\xa0\x80"), nil, false}, // surrogate
+		{str("h\xe9llo"), str("ISO-8859-1"), true},
+		{str("héllo"), str("ASCII"), false},
+		{types.NewArray(types.NewArrayFromSlice(stringValues("a", "é"))), nil, true},
+		{types.NewArray(types.NewArrayFromSlice(stringValues("a", "\xe9"))), nil, false},
+	}
+
+	for _, tt := range tests {
+		result, err := MbCheckEncoding(tt.value, tt.encoding)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.ToBool() != tt.expected {
+			t.Errorf("MbCheckEncoding(%v, %v) = %v, want %v", tt.value, tt.encoding, result.ToBool(), tt.expected)
+		}
+	}
+
+	_, err := MbCheckEncoding(str("a"), str("bogus"))
+	if err == nil || err.Error() != `Argument #2 ($encoding) must be a valid encoding, "bogus" given` {
+		t.Errorf("Expected an encoding error, got %v", err)
+	}
+}
+
+func TestMbInternalEncoding(t *testing.T) {
+	defer SetInternalEncoding(UTF8)
+
+	result, _ := MbInternalEncoding()
+	if result.ToString() != "UTF-8" {
+		t.Errorf("Expected UTF-8 as the default internal encoding, got %v", result)
+	}
+
+	result, err := MbInternalEncoding(str("latin1"))
+	if err != nil || !result.ToBool() {
+		t.Fatalf("Expected the encoding to be set, got %v, %v", result, err)
+	}
+	result, _ = MbInternalEncoding()
+	if result.ToString() != "ISO-8859-1" {
+		t.Errorf("Expected ISO-8859-1, got %v", result)
+	}
+
+	// The internal encoding is the default of the other functions
+	length, _ := MbStrlen(str("日本"))
+	if length.ToInt() != 6 {
+		t.Errorf("Expected mb_strlen to count bytes in ISO-8859-1, got %d", length.ToInt())
+	}
+
+	if _, err := MbInternalEncoding(str("bogus")); err == nil {
+		t.Errorf("Expected an error for an unknown encoding")
+	}
+}
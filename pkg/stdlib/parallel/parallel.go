@@ -0,0 +1,354 @@
+package parallel
+
+// Package parallel implements the explicit parallelism API exposed to PHP in
+// the parallel\ namespace: tasks that run closures on their own goroutine,
+// futures for their results, and channels to communicate between tasks.
+//
+// Tasks share nothing with the code that started them. Every value that
+// crosses a goroutine boundary (task arguments, results, channel messages)
+// is copied with Transfer.
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ErrClosed is returned when sending to or receiving from a closed channel
+var ErrClosed = errors.New("channel is closed")
+
+// ============================================================================
+// Value Transfer
+// ============================================================================
+
+// Transferable is implemented by the engine payload (Object.Internal) of
+// built-in objects that may cross goroutine boundaries. Transfer returns the
+// payload for the copy: shared handles such as channels return themselves.
+type Transferable interface {
+	Transfer() (interface{}, error)
+}
+
+// Transfer copies a value so it can be used on another goroutine. Scalars
+// and strings are immutable and shared, arrays and objects are copied
+// recursively and references are replaced by the values they point to.
+// Objects keep their identity within one transfer, so cycles and aliases
+// survive. Built-in objects are only transferable when their payload
+// implements Transferable.
+func Transfer(value *types.Value) (*types.Value, error) {
+	t := &transfer{objects: make(map[*types.Object]*types.Object)}
+	return t.value(value)
+}
+
+// transfer tracks the objects already copied by one Transfer call
+type transfer struct {
+	objects map[*types.Object]*types.Object
+}
+
+func (t *transfer) value(value *types.Value) (*types.Value, error) {
+	if value == nil {
+		return types.NewNull(), nil
+	}
+	value = value.Deref()
+
+	switch value.Type() {
+	case types.TypeArray:
+		copied := types.NewArrayWithCapacity(value.ToArray().Len())
+		var err error
+		value.ToArray().Each(func(key, element *types.Value) bool {
+			var v *types.Value
+			if v, err = t.value(element); err != nil {
+				return false
+			}
+			copied.Set(key, v)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		return types.NewArray(copied), nil
+
+	case types.TypeObject:
+		obj, err := t.object(value.ToObject())
+		if err != nil {
+			return nil, err
+		}
+		return types.NewObject(obj), nil
+
+	case types.TypeResource:
+		return nil, fmt.Errorf("resources cannot be transferred between tasks")
+
+	default:
+		return value, nil
+	}
+}
+
+func (t *transfer) object(obj *types.Object) (*types.Object, error) {
+	if copied, ok := t.objects[obj]; ok {
+		return copied, nil
+	}
+
+	copied := &types.Object{
+		ClassName:  obj.ClassName,
+		ClassEntry: obj.ClassEntry,
+		Properties: make(map[string]*types.Property, len(obj.Properties)),
+		ObjectID:   types.NextObjectID(),
+
+		PropertyOrder: append([]string(nil), obj.PropertyOrder...),
+	}
+	if obj.Internal != nil {
+		transferable, ok := obj.Internal.(Transferable)
+		if !ok {
+			return nil, fmt.Errorf("objects of class %s cannot be transferred between tasks", obj.ClassName)
+		}
+		internal, err := transferable.Transfer()
+		if err != nil {
+			return nil, err
+		}
+		copied.Internal = internal
+	}
+	t.objects[obj] = copied
+
+	for name, prop := range obj.Properties {
+		value, err := t.value(prop.Value)
+		if err != nil {
+			return nil, err
+		}
+		p := *prop
+		p.Value = value
+		copied.Properties[name] = &p
+	}
+	return copied, nil
+}
+
+// ============================================================================
+// Channels
+// ============================================================================
+
+// Channel is a FIFO queue of values between tasks. An unbuffered channel
+// (capacity 0) blocks the sender until a receiver takes the value. A typed
+// channel only accepts values of its element type.
+type Channel struct {
+	name     string
+	elemType string // Type declaration values must satisfy ("" accepts any value)
+	capacity int
+	values   chan *types.Value
+	closed   chan struct{}
+	once     sync.Once
+}
+
+// NewChannel creates an anonymous channel that accepts any value
+func NewChannel(capacity int) *Channel {
+	return NewTypedChannel(capacity, "")
+}
+
+// NewTypedChannel creates an anonymous channel whose values must match
+// elemType, a PHP type declaration such as "int", "?string" or "array"
+func NewTypedChannel(capacity int, elemType string) *Channel {
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &Channel{
+		elemType: elemType,
+		capacity: capacity,
+		values:   make(chan *types.Value, capacity),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Name returns the name of a named channel ("" for anonymous channels)
+func (c *Channel) Name() string {
+	return c.name
+}
+
+// Type returns the element type of a typed channel ("" when untyped)
+func (c *Channel) Type() string {
+	return c.elemType
+}
+
+// Capacity returns the buffer size of the channel
+func (c *Channel) Capacity() int {
+	return c.capacity
+}
+
+// Len returns the number of buffered values
+func (c *Channel) Len() int {
+	return len(c.values)
+}
+
+// Send copies a value into the channel, blocking while the buffer is full
+func (c *Channel) Send(value *types.Value) error {
+	if c.IsClosed() {
+		return ErrClosed
+	}
+	if c.elemType != "" && !types.IsTypeCompatible(c.elemType, typeName(value)) {
+		return fmt.Errorf("channel of type %s cannot accept a value of type %s", c.elemType, typeName(value))
+	}
+
+	copied, err := Transfer(value)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case c.values <- copied:
+		return nil
+	case <-c.closed:
+		return ErrClosed
+	}
+}
+
+// Recv takes the next value from the channel, blocking until one is sent.
+// Values buffered before the channel was closed can still be received.
+func (c *Channel) Recv() (*types.Value, error) {
+	select {
+	case value := <-c.values:
+		return value, nil
+	case <-c.closed:
+		select {
+		case value := <-c.values:
+			return value, nil
+		default:
+			return nil, ErrClosed
+		}
+	}
+}
+
+// Close closes the channel. Blocked senders and receivers fail with ErrClosed.
+func (c *Channel) Close() error {
+	err := ErrClosed
+	c.once.Do(func() {
+		close(c.closed)
+		err = nil
+	})
+	return err
+}
+
+// IsClosed reports whether the channel was closed
+func (c *Channel) IsClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// typeName returns the type of a value as written in type declarations
+func typeName(value *types.Value) string {
+	value = value.Deref()
+	switch value.Type() {
+	case types.TypeBool:
+		return "bool"
+	case types.TypeInt:
+		return "int"
+	case types.TypeFloat:
+		return "float"
+	case types.TypeString:
+		return "string"
+	case types.TypeArray:
+		return "array"
+	case types.TypeObject:
+		return value.ToObject().ClassName
+	case types.TypeResource:
+		return "resource"
+	default:
+		return "null"
+	}
+}
+
+// Transfer shares the channel: both sides of a transfer use the same queue
+func (c *Channel) Transfer() (interface{}, error) {
+	return c, nil
+}
+
+// Named channels are visible to every task of the process
+var (
+	namedMu       sync.Mutex
+	namedChannels = make(map[string]*Channel)
+)
+
+// MakeChannel creates a named channel that other tasks can open by name
+func MakeChannel(name string, capacity int, elemType string) (*Channel, error) {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+
+	if _, exists := namedChannels[name]; exists {
+		return nil, fmt.Errorf("channel named %s already exists", name)
+	}
+	c := NewTypedChannel(capacity, elemType)
+	c.name = name
+	namedChannels[name] = c
+	return c, nil
+}
+
+// OpenChannel returns the named channel created by MakeChannel
+func OpenChannel(name string) (*Channel, error) {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+
+	c, ok := namedChannels[name]
+	if !ok {
+		return nil, fmt.Errorf("channel named %s not found", name)
+	}
+	return c, nil
+}
+
+// ============================================================================
+// Tasks and Futures
+// ============================================================================
+
+// Task is the body of a parallel task. It runs on its own goroutine and
+// must only use values that were transferred to it.
+type Task func() (*types.Value, error)
+
+// Future is the result of a task
+type Future struct {
+	done  chan struct{}
+	value *types.Value
+	err   error
+}
+
+// Go starts a task on a new goroutine. A panic in the task is reported as
+// its error.
+func Go(task Task) *Future {
+	f := &Future{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		defer func() {
+			if r := recover(); r != nil {
+				f.err = fmt.Errorf("task panicked: %v", r)
+			}
+		}()
+
+		value, err := task()
+		if err != nil {
+			f.err = err
+			return
+		}
+		f.value, f.err = Transfer(value)
+	}()
+	return f
+}
+
+// Value waits for the task to finish and returns its result
+func (f *Future) Value() (*types.Value, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+// Done reports whether the task has finished
+func (f *Future) Done() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Transfer shares the future, so any task can wait for the result
+func (f *Future) Transfer() (interface{}, error) {
+	return f, nil
+}
@@ -0,0 +1,176 @@
+package parallel
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Transfer Tests
+// ============================================================================
+
+func TestTransferCopiesArrays(t *testing.T) {
+	inner := types.NewArrayFromSlice([]*types.Value{types.NewInt(1)})
+	outer := types.NewEmptyArray()
+	outer.Set(types.NewString("inner"), types.NewArray(inner))
+	outer.Set(types.NewString("ref"), types.NewReference(types.NewInt(7)))
+
+	copied, err := Transfer(types.NewArray(outer))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inner.Append(types.NewInt(2))
+	nested, _ := copied.ToArray().Get(types.NewString("inner"))
+	if nested.ToArray().Len() != 1 {
+		t.Errorf("Expected the transferred array to be independent, got %s", nested.ToArray())
+	}
+	if ref, _ := copied.ToArray().Get(types.NewString("ref")); ref.IsReference() || ref.ToInt() != 7 {
+		t.Errorf("Expected references to be replaced by their value, got %v", ref)
+	}
+}
+
+func TestTransferObjects(t *testing.T) {
+	obj := types.NewObjectInstance("Node")
+	obj.Properties["self"] = &types.Property{Value: types.NewObject(obj)}
+	obj.Properties["name"] = &types.Property{Value: types.NewString("a")}
+
+	copied, err := Transfer(types.NewObject(obj))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c := copied.ToObject()
+	if c == obj || c.ObjectID == obj.ObjectID {
+		t.Error("Expected a new object")
+	}
+	if c.Properties["self"].Value.ToObject() != c {
+		t.Error("Expected the cycle to point at the copy")
+	}
+
+	closure := types.NewObjectInstance("Closure")
+	closure.Internal = struct{}{}
+	if _, err := Transfer(types.NewObject(closure)); err == nil {
+		t.Error("Expected objects with a non-transferable payload to be rejected")
+	}
+
+	channel := types.NewObjectInstance("parallel\\Channel")
+	channel.Internal = NewChannel(0)
+	copied, err = Transfer(types.NewObject(channel))
+	if err != nil || copied.ToObject().Internal != channel.Internal {
+		t.Errorf("Expected channels to be shared, got %v", err)
+	}
+}
+
+// ============================================================================
+// Channel Tests
+// ============================================================================
+
+func TestChannelBuffered(t *testing.T) {
+	c := NewChannel(2)
+	for i := int64(1); i <= 2; i++ {
+		if err := c.Send(types.NewInt(i)); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 buffered values, got %d", c.Len())
+	}
+
+	c.Close()
+	if err := c.Send(types.NewInt(3)); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed sending to a closed channel, got %v", err)
+	}
+
+	// Buffered values survive closing
+	for i := int64(1); i <= 2; i++ {
+		if v, err := c.Recv(); err != nil || v.ToInt() != i {
+			t.Errorf("Expected %d, got %v (%v)", i, v, err)
+		}
+	}
+	if _, err := c.Recv(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed once drained, got %v", err)
+	}
+	if err := c.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected closing twice to fail, got %v", err)
+	}
+}
+
+func TestChannelUnbufferedAcrossGoroutines(t *testing.T) {
+	c := NewChannel(0)
+	arr := types.NewArrayFromSlice([]*types.Value{types.NewInt(1)})
+
+	future := Go(func() (*types.Value, error) {
+		sum := int64(0)
+		for {
+			v, err := c.Recv()
+			if errors.Is(err, ErrClosed) {
+				return types.NewInt(sum), nil
+			}
+			v.ToArray().Append(types.NewInt(100))
+			sum += int64(v.ToArray().Len())
+		}
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := c.Send(types.NewArray(arr)); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	c.Close()
+
+	result, err := future.Value()
+	if err != nil || result.ToInt() != 6 {
+		t.Errorf("Expected each receiver to get its own copy (sum 6), got %v (%v)", result, err)
+	}
+	if arr.Len() != 1 {
+		t.Errorf("Expected the sent array to be unchanged, got %s", arr)
+	}
+}
+
+func TestTypedChannel(t *testing.T) {
+	c := NewTypedChannel(1, "int")
+	if err := c.Send(types.NewString("x")); err == nil {
+		t.Error("Expected a typed channel to reject a string")
+	}
+	if err := c.Send(types.NewInt(1)); err != nil {
+		t.Errorf("Expected a typed channel to accept an int, got %v", err)
+	}
+}
+
+func TestNamedChannels(t *testing.T) {
+	name := fmt.Sprintf("%s-%p", t.Name(), t)
+	made, err := MakeChannel(name, 1, "")
+	if err != nil {
+		t.Fatalf("MakeChannel: %v", err)
+	}
+	if _, err := MakeChannel(name, 1, ""); err == nil {
+		t.Error("Expected making a channel twice to fail")
+	}
+	opened, err := OpenChannel(name)
+	if err != nil || opened != made {
+		t.Errorf("Expected to open the same channel, got %v", err)
+	}
+	if _, err := OpenChannel(name + "-missing"); err == nil {
+		t.Error("Expected opening an unknown channel to fail")
+	}
+}
+
+// ============================================================================
+// Future Tests
+// ============================================================================
+
+func TestFutureReportsPanics(t *testing.T) {
+	future := Go(func() (*types.Value, error) {
+		panic("boom")
+	})
+	if _, err := future.Value(); err == nil {
+		t.Error("Expected a panic to be reported as an error")
+	}
+	if !future.Done() {
+		t.Error("Expected the future to be done")
+	}
+}
@@ -0,0 +1,642 @@
+package pcre
+
+import (
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/krizos/php-go/pkg/stdlib"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// PCRE Constants
+// ============================================================================
+
+const (
+	PREG_PATTERN_ORDER        = 1
+	PREG_SET_ORDER            = 2
+	PREG_OFFSET_CAPTURE       = 1 << 8 // 256
+	PREG_UNMATCHED_AS_NULL    = 1 << 9 // 512
+	PREG_SPLIT_NO_EMPTY       = 1 << 0 // 1
+	PREG_SPLIT_DELIM_CAPTURE  = 1 << 1 // 2
+	PREG_SPLIT_OFFSET_CAPTURE = 1 << 2 // 4
+	PREG_GREP_INVERT          = 1
+)
+
+// PCRE error constants
+const (
+	PREG_NO_ERROR = iota
+	PREG_INTERNAL_ERROR
+	PREG_BACKTRACK_LIMIT_ERROR
+	PREG_RECURSION_LIMIT_ERROR
+	PREG_BAD_UTF8_ERROR
+	PREG_BAD_UTF8_OFFSET_ERROR
+	PREG_JIT_STACKLIMIT_ERROR
+)
+
+// PCRE_VERSION identifies the regex engine
+const PCRE_VERSION = "RE2 (Go regexp)"
+
+// Constants returns the constants defined by the PCRE extension
+func Constants() map[string]*types.Value {
+	constants := map[string]*types.Value{
+		"PCRE_VERSION": types.NewString(PCRE_VERSION),
+	}
+	for name, value := range map[string]int64{
+		"PREG_PATTERN_ORDER":        PREG_PATTERN_ORDER,
+		"PREG_SET_ORDER":            PREG_SET_ORDER,
+		"PREG_OFFSET_CAPTURE":       PREG_OFFSET_CAPTURE,
+		"PREG_UNMATCHED_AS_NULL":    PREG_UNMATCHED_AS_NULL,
+		"PREG_SPLIT_NO_EMPTY":       PREG_SPLIT_NO_EMPTY,
+		"PREG_SPLIT_DELIM_CAPTURE":  PREG_SPLIT_DELIM_CAPTURE,
+		"PREG_SPLIT_OFFSET_CAPTURE": PREG_SPLIT_OFFSET_CAPTURE,
+		"PREG_GREP_INVERT":          PREG_GREP_INVERT,
+
+		"PREG_NO_ERROR":              PREG_NO_ERROR,
+		"PREG_INTERNAL_ERROR":        PREG_INTERNAL_ERROR,
+		"PREG_BACKTRACK_LIMIT_ERROR": PREG_BACKTRACK_LIMIT_ERROR,
+		"PREG_RECURSION_LIMIT_ERROR": PREG_RECURSION_LIMIT_ERROR,
+		"PREG_BAD_UTF8_ERROR":        PREG_BAD_UTF8_ERROR,
+		"PREG_BAD_UTF8_OFFSET_ERROR": PREG_BAD_UTF8_OFFSET_ERROR,
+		"PREG_JIT_STACKLIMIT_ERROR":  PREG_JIT_STACKLIMIT_ERROR,
+	} {
+		constants[name] = types.NewInt(value)
+	}
+	return constants
+}
+
+// lastPregError is the error of the last preg_* call (atomic, since
+// parallel tasks call preg functions concurrently)
+var lastPregError atomic.Int64
+
+// setError records the result of a preg_* call
+func setError(code int) {
+	lastPregError.Store(int64(code))
+}
+
+// ============================================================================
+// Matching Helpers
+// ============================================================================
+
+// prepare compiles a pattern and validates the subject and start offset.
+// It returns false and records the error when matching cannot start.
+func prepare(pattern *types.Value, subject string, offset int) (*Regex, bool) {
+	r, err := Compile(pattern.ToString())
+	if err != nil {
+		setError(PREG_INTERNAL_ERROR)
+		return nil, false
+	}
+	if r.utf8 {
+		if !utf8.ValidString(subject) {
+			setError(PREG_BAD_UTF8_ERROR)
+			return nil, false
+		}
+		if offset > 0 && offset < len(subject) && !utf8.RuneStart(subject[offset]) {
+			setError(PREG_BAD_UTF8_OFFSET_ERROR)
+			return nil, false
+		}
+	}
+	setError(PREG_NO_ERROR)
+	return r, true
+}
+
+// startOffset resolves a PHP offset argument; negative offsets count from
+// the end of the subject
+func startOffset(args []*types.Value, index int, subject string) (int, bool) {
+	if len(args) <= index || args[index] == nil {
+		return 0, true
+	}
+	offset := int(args[index].ToInt())
+	if offset < 0 {
+		offset += len(subject)
+		if offset < 0 {
+			offset = 0
+		}
+	}
+	if offset > len(subject) {
+		setError(PREG_INTERNAL_ERROR)
+		return 0, false
+	}
+	return offset, true
+}
+
+// intArg returns an optional integer argument
+func intArg(args []*types.Value, index int, def int) int {
+	if len(args) <= index || args[index] == nil || args[index].IsNull() {
+		return def
+	}
+	return int(args[index].ToInt())
+}
+
+// find returns the first match at or after offset as byte offsets into the
+// subject (pairs of start and end per group, -1 for unmatched groups)
+func (r *Regex) find(subject string, offset int) []int {
+	return shift(r.re.FindStringSubmatchIndex(subject[offset:]), offset)
+}
+
+// findAll returns up to limit matches (all when limit < 0) after offset
+func (r *Regex) findAll(subject string, offset, limit int) [][]int {
+	locs := r.re.FindAllStringSubmatchIndex(subject[offset:], limit)
+	for _, loc := range locs {
+		shift(loc, offset)
+	}
+	return locs
+}
+
+// shift moves match positions found in a suffix of the subject
+func shift(loc []int, offset int) []int {
+	if offset == 0 {
+		return loc
+	}
+	for i := range loc {
+		if loc[i] >= 0 {
+			loc[i] += offset
+		}
+	}
+	return loc
+}
+
+// groupValue returns group g of a match as PHP reports it
+func groupValue(subject string, loc []int, g int, flags int) *types.Value {
+	start, end := loc[2*g], loc[2*g+1]
+
+	var value *types.Value
+	switch {
+	case start >= 0:
+		value = types.NewString(subject[start:end])
+	case flags&PREG_UNMATCHED_AS_NULL != 0:
+		value = types.NewNull()
+	default:
+		value = types.NewString("")
+	}
+
+	if flags&PREG_OFFSET_CAPTURE != 0 {
+		return types.NewArray(types.NewArrayFromSlice([]*types.Value{value, types.NewInt(int64(start))}))
+	}
+	return value
+}
+
+// matchArray builds the $matches array of one match. Named groups appear
+// under their name and their number. Trailing groups that did not match are
+// left out, unless PREG_UNMATCHED_AS_NULL is set.
+func (r *Regex) matchArray(subject string, loc []int, flags int) *types.Array {
+	count := len(loc) / 2
+	if flags&PREG_UNMATCHED_AS_NULL == 0 {
+		for count > 1 && loc[2*(count-1)] < 0 {
+			count--
+		}
+	}
+
+	matches := types.NewArrayWithCapacity(count)
+	for g := 0; g < count; g++ {
+		value := groupValue(subject, loc, g, flags)
+		if name := r.names[g]; name != "" {
+			matches.Set(types.NewString(name), value)
+		}
+		matches.Set(types.NewInt(int64(g)), value)
+	}
+	return matches
+}
+
+// ============================================================================
+// Matching
+// ============================================================================
+
+// PregMatch performs a regular expression match and returns 1, 0 or false
+// together with the $matches array
+// preg_match(string $pattern, string $subject, array &$matches = null, int $flags = 0, int $offset = 0): int|false
+func PregMatch(pattern, subject *types.Value, args ...*types.Value) (*types.Value, *types.Value) {
+	empty := types.NewArray(types.NewEmptyArray())
+	s := subject.ToString()
+	flags := intArg(args, 0, 0)
+
+	offset, ok := startOffset(args, 1, s)
+	if !ok {
+		return types.NewBool(false), empty
+	}
+	r, ok := prepare(pattern, s, offset)
+	if !ok {
+		return types.NewBool(false), empty
+	}
+
+	loc := r.find(s, offset)
+	if loc == nil {
+		return types.NewInt(0), empty
+	}
+	return types.NewInt(1), types.NewArray(r.matchArray(s, loc, flags))
+}
+
+// PregMatchAll performs a global regular expression match and returns the
+// number of matches (or false) together with the $matches array
+// preg_match_all(string $pattern, string $subject, array &$matches = null, int $flags = 0, int $offset = 0): int|false
+func PregMatchAll(pattern, subject *types.Value, args ...*types.Value) (*types.Value, *types.Value) {
+	empty := types.NewArray(types.NewEmptyArray())
+	s := subject.ToString()
+	flags := intArg(args, 0, 0)
+	if flags&(PREG_PATTERN_ORDER|PREG_SET_ORDER) == PREG_PATTERN_ORDER|PREG_SET_ORDER {
+		// Invalid flags: PREG_PATTERN_ORDER and PREG_SET_ORDER are exclusive
+		setError(PREG_INTERNAL_ERROR)
+		return types.NewBool(false), empty
+	}
+
+	offset, ok := startOffset(args, 1, s)
+	if !ok {
+		return types.NewBool(false), empty
+	}
+	r, ok := prepare(pattern, s, offset)
+	if !ok {
+		return types.NewBool(false), empty
+	}
+
+	locs := r.findAll(s, offset, -1)
+
+	if flags&PREG_SET_ORDER != 0 {
+		sets := types.NewArrayWithCapacity(len(locs))
+		for _, loc := range locs {
+			sets.Append(types.NewArray(r.matchArray(s, loc, flags)))
+		}
+		return types.NewInt(int64(len(locs))), types.NewArray(sets)
+	}
+
+	// PREG_PATTERN_ORDER: one list per group
+	matches := types.NewEmptyArray()
+	for g := 0; g <= r.NumGroups(); g++ {
+		list := types.NewArrayWithCapacity(len(locs))
+		for _, loc := range locs {
+			list.Append(groupValue(s, loc, g, flags))
+		}
+		if name := r.names[g]; name != "" {
+			matches.Set(types.NewString(name), types.NewArray(list))
+		}
+		matches.Set(types.NewInt(int64(g)), types.NewArray(list.Copy()))
+	}
+	return types.NewInt(int64(len(locs))), types.NewArray(matches)
+}
+
+// PregGrep returns the entries of an array that match a pattern, keeping
+// their keys
+// preg_grep(string $pattern, array $array, int $flags = 0): array|false
+func PregGrep(pattern, input *types.Value, args ...*types.Value) *types.Value {
+	invert := intArg(args, 0, 0)&PREG_GREP_INVERT != 0
+	r, err := Compile(pattern.ToString())
+	if err != nil {
+		setError(PREG_INTERNAL_ERROR)
+		return types.NewBool(false)
+	}
+	setError(PREG_NO_ERROR)
+
+	result := types.NewEmptyArray()
+	if input == nil || !input.IsArray() {
+		return types.NewArray(result)
+	}
+	input.ToArray().Each(func(key, value *types.Value) bool {
+		if r.re.MatchString(value.ToString()) != invert {
+			result.Set(key, value)
+		}
+		return true
+	})
+	return types.NewArray(result)
+}
+
+// ============================================================================
+// Replacement
+// ============================================================================
+
+// PregReplace performs a regular expression search and replace and returns
+// the result (null on failure) and the number of replacements
+// preg_replace(string|array $pattern, string|array $replacement, string|array $subject, int $limit = -1, int &$count = null): string|array|null
+func PregReplace(pattern, replacement, subject *types.Value, args ...*types.Value) (*types.Value, *types.Value) {
+	limit := intArg(args, 0, -1)
+	count := 0
+
+	replace := func(s string) (string, bool) {
+		patterns, replacements := patternList(pattern), replacementList(replacement, pattern)
+		for i, p := range patterns {
+			r, ok := prepare(p, s, 0)
+			if !ok {
+				return "", false
+			}
+			repl := ""
+			if i < len(replacements) {
+				repl = replacements[i]
+			}
+			var n int
+			s, n = r.replace(s, limit, func(loc []int) string {
+				return expandReplacement(repl, s, loc)
+			})
+			count += n
+		}
+		return s, true
+	}
+
+	result := mapSubject(subject, replace)
+	return result, types.NewInt(int64(count))
+}
+
+// PregReplaceCallback performs a regular expression search and replace
+// using a callback that receives the $matches array of each match
+// preg_replace_callback(string|array $pattern, callable $callback, string|array $subject, int $limit = -1, int &$count = null, int $flags = 0): string|array|null
+func PregReplaceCallback(caller stdlib.Caller, pattern, callback, subject *types.Value, args ...*types.Value) (*types.Value, *types.Value, error) {
+	limit := intArg(args, 0, -1)
+	flags := intArg(args, 1, 0)
+	count := 0
+	var callErr error
+
+	replace := func(s string) (string, bool) {
+		for _, p := range patternList(pattern) {
+			r, ok := prepare(p, s, 0)
+			if !ok {
+				return "", false
+			}
+			var n int
+			s, n = r.replace(s, limit, func(loc []int) string {
+				if callErr != nil {
+					return ""
+				}
+				matches := types.NewArray(r.matchArray(s, loc, flags))
+				result, err := caller.CallCallable(callback, []*types.Value{matches})
+				if err != nil {
+					callErr = err
+					return ""
+				}
+				return result.ToString()
+			})
+			count += n
+		}
+		return s, true
+	}
+
+	result := mapSubject(subject, replace)
+	if callErr != nil {
+		return nil, nil, callErr
+	}
+	return result, types.NewInt(int64(count)), nil
+}
+
+// replace substitutes up to limit matches using the given function
+func (r *Regex) replace(subject string, limit int, substitute func(loc []int) string) (string, int) {
+	if limit == 0 {
+		return subject, 0
+	}
+	locs := r.findAll(subject, 0, limit)
+	if len(locs) == 0 {
+		return subject, 0
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, loc := range locs {
+		out.WriteString(subject[last:loc[0]])
+		out.WriteString(substitute(loc))
+		last = loc[1]
+	}
+	out.WriteString(subject[last:])
+	return out.String(), len(locs)
+}
+
+// expandReplacement substitutes the $n, ${n} and \n references of a
+// replacement string. References to groups that do not exist or did not
+// match are replaced by an empty string; a backslash before $ or \ makes
+// it literal.
+func expandReplacement(repl, subject string, loc []int) string {
+	if !strings.ContainsAny(repl, `\$`) {
+		return repl
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(repl); i++ {
+		c := repl[i]
+		if c != '\\' && c != '$' {
+			out.WriteByte(c)
+			continue
+		}
+
+		if c == '\\' && i+1 < len(repl) && (repl[i+1] == '\\' || repl[i+1] == '$') {
+			out.WriteByte(repl[i+1])
+			i++
+			continue
+		}
+
+		group, width := parseBackref(repl[i:])
+		if width == 0 {
+			out.WriteByte(c)
+			continue
+		}
+		if 2*group+1 < len(loc) && loc[2*group] >= 0 {
+			out.WriteString(subject[loc[2*group]:loc[2*group+1]])
+		}
+		i += width - 1
+	}
+	return out.String()
+}
+
+// parseBackref parses a group reference ($1, ${12}, \3) of at most two
+// digits at the start of s and returns the group and the reference length
+func parseBackref(s string) (int, int) {
+	i := 1
+	braced := s[0] == '$' && i < len(s) && s[i] == '{'
+	if braced {
+		i++
+	}
+
+	group, digits := 0, 0
+	for i < len(s) && digits < 2 && s[i] >= '0' && s[i] <= '9' {
+		group = group*10 + int(s[i]-'0')
+		i++
+		digits++
+	}
+	if digits == 0 {
+		return 0, 0
+	}
+	if braced {
+		if i >= len(s) || s[i] != '}' {
+			return 0, 0
+		}
+		i++
+	}
+	return group, i
+}
+
+// patternList returns the patterns of a string|array pattern argument
+func patternList(pattern *types.Value) []*types.Value {
+	if pattern.IsArray() {
+		return arrayValues(pattern.ToArray())
+	}
+	return []*types.Value{pattern}
+}
+
+// arrayValues returns the values of an array in order
+func arrayValues(arr *types.Array) []*types.Value {
+	values := make([]*types.Value, 0, arr.Len())
+	arr.Each(func(key, value *types.Value) bool {
+		values = append(values, value)
+		return true
+	})
+	return values
+}
+
+// replacementList returns the replacement for each pattern; a string
+// replacement is used for every pattern
+func replacementList(replacement, pattern *types.Value) []string {
+	patterns := patternList(pattern)
+	list := make([]string, 0, len(patterns))
+	if !replacement.IsArray() {
+		for range patterns {
+			list = append(list, replacement.ToString())
+		}
+		return list
+	}
+	for _, r := range arrayValues(replacement.ToArray()) {
+		list = append(list, r.ToString())
+	}
+	return list
+}
+
+// mapSubject applies a replacement to a string subject, or to every entry
+// of an array subject keeping the keys. Failed entries are left out of an
+// array result; a failed string subject gives null.
+func mapSubject(subject *types.Value, replace func(string) (string, bool)) *types.Value {
+	if subject.IsArray() {
+		result := types.NewEmptyArray()
+		subject.ToArray().Each(func(key, value *types.Value) bool {
+			if s, ok := replace(value.ToString()); ok {
+				result.Set(key, types.NewString(s))
+			}
+			return true
+		})
+		return types.NewArray(result)
+	}
+
+	s, ok := replace(subject.ToString())
+	if !ok {
+		return types.NewNull()
+	}
+	return types.NewString(s)
+}
+
+// ============================================================================
+// Splitting and Quoting
+// ============================================================================
+
+// PregSplit splits a string by a regular expression
+// preg_split(string $pattern, string $subject, int $limit = -1, int $flags = 0): array|false
+func PregSplit(pattern, subject *types.Value, args ...*types.Value) *types.Value {
+	s := subject.ToString()
+	limit := intArg(args, 0, -1)
+	flags := intArg(args, 1, 0)
+	if limit == 0 {
+		limit = -1
+	}
+
+	r, ok := prepare(pattern, s, 0)
+	if !ok {
+		return types.NewBool(false)
+	}
+
+	noEmpty := flags&PREG_SPLIT_NO_EMPTY != 0
+	pieces := types.NewEmptyArray()
+	add := func(start, end int) {
+		if flags&PREG_SPLIT_OFFSET_CAPTURE != 0 {
+			pieces.Append(types.NewArray(types.NewArrayFromSlice([]*types.Value{
+				types.NewString(s[start:end]), types.NewInt(int64(start)),
+			})))
+			return
+		}
+		pieces.Append(types.NewString(s[start:end]))
+	}
+
+	last := 0
+	for _, loc := range r.findAll(s, 0, -1) {
+		if limit == 1 {
+			break
+		}
+
+		if !noEmpty || loc[0] > last {
+			add(last, loc[0])
+			if limit > 0 {
+				limit--
+			}
+		}
+
+		if flags&PREG_SPLIT_DELIM_CAPTURE != 0 {
+			count := len(loc) / 2
+			for count > 1 && loc[2*(count-1)] < 0 {
+				count--
+			}
+			for g := 1; g < count; g++ {
+				start, end := loc[2*g], loc[2*g+1]
+				if start < 0 {
+					start, end = 0, 0
+				}
+				if !noEmpty || end > start {
+					add(start, end)
+				}
+			}
+		}
+		last = loc[1]
+	}
+
+	if !noEmpty || last < len(s) {
+		add(last, len(s))
+	}
+	return types.NewArray(pieces)
+}
+
+// PregQuote escapes regular expression characters
+// preg_quote(string $str, ?string $delimiter = null): string
+func PregQuote(str *types.Value, delimiter ...*types.Value) *types.Value {
+	delim := byte(0)
+	if len(delimiter) > 0 && delimiter[0] != nil && !delimiter[0].IsNull() && delimiter[0].ToString() != "" {
+		delim = delimiter[0].ToString()[0]
+	}
+
+	s := str.ToString()
+	var out strings.Builder
+	out.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == 0:
+			out.WriteString(`\000`)
+			continue
+		case strings.IndexByte(`.\+*?[^]$(){}=!<>|:-#`, c) >= 0, c == delim:
+			out.WriteByte('\\')
+		}
+		out.WriteByte(c)
+	}
+	return types.NewString(out.String())
+}
+
+// ============================================================================
+// Error Handling
+// ============================================================================
+
+// PregLastError returns the error code of the last preg_* call
+// preg_last_error(): int
+func PregLastError() *types.Value {
+	return types.NewInt(lastPregError.Load())
+}
+
+// PregLastErrorMsg returns the error message of the last preg_* call
+// preg_last_error_msg(): string
+func PregLastErrorMsg() *types.Value {
+	switch lastPregError.Load() {
+	case PREG_NO_ERROR:
+		return types.NewString("No error")
+	case PREG_INTERNAL_ERROR:
+		return types.NewString("Internal error")
+	case PREG_BACKTRACK_LIMIT_ERROR:
+		return types.NewString("Backtrack limit exhausted")
+	case PREG_RECURSION_LIMIT_ERROR:
+		return types.NewString("Recursion limit exhausted")
+	case PREG_BAD_UTF8_ERROR:
+		return types.NewString("Malformed UTF-8 characters, possibly incorrectly encoded")
+	case PREG_BAD_UTF8_OFFSET_ERROR:
+		return types.NewString("The offset did not correspond to the beginning of a valid UTF-8 code point")
+	case PREG_JIT_STACKLIMIT_ERROR:
+		return types.NewString("JIT stack limit exhausted")
+	default:
+		return types.NewString("Unknown error")
+	}
+}
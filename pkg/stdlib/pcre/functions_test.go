@@ -0,0 +1,248 @@
+package pcre
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/stdlib"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// get returns an entry of an array value by int or string key
+func get(arr *types.Value, key interface{}) *types.Value {
+	var k *types.Value
+	switch key := key.(type) {
+	case int:
+		k = types.NewInt(int64(key))
+	case string:
+		k = types.NewString(key)
+	}
+	v, _ := arr.ToArray().Get(k)
+	return v
+}
+
+// strs converts Go strings to PHP string values
+func strs(values ...string) []*types.Value {
+	result := make([]*types.Value, len(values))
+	for i, v := range values {
+		result[i] = types.NewString(v)
+	}
+	return result
+}
+
+// list returns the string values of an array, in order
+func list(arr *types.Value) []string {
+	var result []string
+	for _, v := range arrayValues(arr.ToArray()) {
+		result = append(result, v.ToString())
+	}
+	return result
+}
+
+// ============================================================================
+// Matching Tests
+// ============================================================================
+
+func TestPregMatch(t *testing.T) {
+	result, matches := PregMatch(types.NewString(`/(\d+)-(?P<word>[a-z]+)/`), types.NewString("id 42-abc!"))
+	if result.ToInt() != 1 {
+		t.Fatalf("Expected 1, got %v", result)
+	}
+	if get(matches, 0).ToString() != "42-abc" || get(matches, 1).ToString() != "42" {
+		t.Errorf("Unexpected numbered groups: %s", matches.ToArray())
+	}
+	if get(matches, "word").ToString() != "abc" || get(matches, 2).ToString() != "abc" {
+		t.Errorf("Expected the named group under its name and number: %s", matches.ToArray())
+	}
+
+	result, matches = PregMatch(types.NewString("/x/"), types.NewString("abc"))
+	if result.ToInt() != 0 || matches.ToArray().Len() != 0 {
+		t.Errorf("Expected no match, got %v %s", result, matches.ToArray())
+	}
+}
+
+func TestPregMatchFlags(t *testing.T) {
+	pattern := types.NewString("/(a)(b)?(c)?/")
+
+	_, matches := PregMatch(pattern, types.NewString("xa"))
+	if matches.ToArray().Len() != 2 {
+		t.Errorf("Expected trailing unmatched groups to be left out, got %s", matches.ToArray())
+	}
+
+	_, matches = PregMatch(pattern, types.NewString("xa"), types.NewInt(PREG_UNMATCHED_AS_NULL))
+	if matches.ToArray().Len() != 4 || !get(matches, 3).IsNull() {
+		t.Errorf("Expected unmatched groups as null, got %s", matches.ToArray())
+	}
+
+	_, matches = PregMatch(pattern, types.NewString("xa"), types.NewInt(PREG_OFFSET_CAPTURE))
+	if pair := get(matches, 1); get(pair, 0).ToString() != "a" || get(pair, 1).ToInt() != 1 {
+		t.Errorf("Expected [match, offset] pairs, got %s", matches.ToArray())
+	}
+
+	result, matches := PregMatch(types.NewString("/a/"), types.NewString("a-a"), types.NewInt(PREG_OFFSET_CAPTURE), types.NewInt(1))
+	if result.ToInt() != 1 || get(get(matches, 0), 1).ToInt() != 2 {
+		t.Errorf("Expected the match after the offset at 2, got %s", matches.ToArray())
+	}
+}
+
+func TestPregMatchAll(t *testing.T) {
+	pattern := types.NewString(`/(?<k>\w)=(\d)/`)
+	subject := types.NewString("a=1, b=2, c=3")
+
+	result, matches := PregMatchAll(pattern, subject)
+	if result.ToInt() != 3 {
+		t.Fatalf("Expected 3 matches, got %v", result)
+	}
+	if got := strings.Join(list(get(matches, "k")), ","); got != "a,b,c" {
+		t.Errorf("Expected named group list a,b,c, got %s", got)
+	}
+	if got := strings.Join(list(get(matches, 2)), ","); got != "1,2,3" {
+		t.Errorf("Expected group 2 list 1,2,3, got %s", got)
+	}
+
+	_, matches = PregMatchAll(pattern, subject, types.NewInt(PREG_SET_ORDER))
+	if set := get(matches, 1); get(set, 0).ToString() != "b=2" || get(set, "k").ToString() != "b" {
+		t.Errorf("Expected the second set to be b=2, got %s", matches.ToArray())
+	}
+
+	result, _ = PregMatchAll(pattern, subject, types.NewInt(PREG_SET_ORDER|PREG_PATTERN_ORDER))
+	if result.IsInt() || PregLastError().ToInt() != PREG_INTERNAL_ERROR {
+		t.Errorf("Expected false for exclusive flags, got %v", result)
+	}
+}
+
+func TestPregGrep(t *testing.T) {
+	input := types.NewArray(types.NewArrayFromSlice(strs("apple", "42", "pear", "7")))
+
+	result := PregGrep(types.NewString(`/^\d+$/`), input)
+	if result.ToArray().Len() != 2 || get(result, 1).ToString() != "42" || get(result, 3).ToString() != "7" {
+		t.Errorf("Expected the numbers with their keys, got %s", result.ToArray())
+	}
+
+	result = PregGrep(types.NewString(`/^\d+$/`), input, types.NewInt(PREG_GREP_INVERT))
+	if got := strings.Join(list(result), ","); got != "apple,pear" {
+		t.Errorf("Expected apple,pear, got %s", got)
+	}
+}
+
+// ============================================================================
+// Replacement Tests
+// ============================================================================
+
+func TestPregReplace(t *testing.T) {
+	tests := []struct {
+		pattern, replacement, subject, expected string
+	}{
+		{`/(\w+) (\w+)/`, "$2 $1", "hello world", "world hello"},
+		{`/(\w+) (\w+)/`, `\2-\1`, "hello world", "world-hello"},
+		{`/(\d)/`, "${1}0", "a1b2", "a10b20"},
+		{`/(a)|(b)/`, "[$2]", "ab", "[][b]"},
+		{`/a/`, `\$1 \\`, "a", `$1 \`},
+		{`/x/`, "y", "abc", "abc"},
+	}
+	for _, tt := range tests {
+		result, _ := PregReplace(types.NewString(tt.pattern), types.NewString(tt.replacement), types.NewString(tt.subject))
+		if result.ToString() != tt.expected {
+			t.Errorf("%s -> %q on %q: expected %q, got %q", tt.pattern, tt.replacement, tt.subject, tt.expected, result.ToString())
+		}
+	}
+}
+
+func TestPregReplaceArrays(t *testing.T) {
+	patterns := types.NewArray(types.NewArrayFromSlice(strs("/a/", "/b/", "/c/")))
+	replacements := types.NewArray(types.NewArrayFromSlice(strs("b", "c")))
+
+	// Patterns apply in turn; missing replacements are empty
+	result, count := PregReplace(patterns, replacements, types.NewString("abcd"))
+	if result.ToString() != "d" || count.ToInt() != 6 {
+		t.Errorf("Expected d with 6 replacements, got %q (%v)", result.ToString(), count)
+	}
+
+	subjects := types.NewEmptyArray()
+	subjects.Set(types.NewString("x"), types.NewString("aaa"))
+	result, count = PregReplace(types.NewString("/a/"), types.NewString("z"), types.NewArray(subjects), types.NewInt(2))
+	if get(result, "x").ToString() != "zza" || count.ToInt() != 2 {
+		t.Errorf("Expected the limit to apply per subject, got %s (%v)", result.ToArray(), count)
+	}
+
+	result, _ = PregReplace(types.NewString("/a/k"), types.NewString(""), types.NewString("a"))
+	if !result.IsNull() {
+		t.Errorf("Expected null for an invalid pattern, got %v", result)
+	}
+}
+
+func TestPregReplaceCallback(t *testing.T) {
+	caller := stdlib.CallerFunc(func(_ *types.Value, args []*types.Value) (*types.Value, error) {
+		return types.NewString(strings.ToUpper(get(args[0], "w").ToString())), nil
+	})
+
+	result, count, err := PregReplaceCallback(caller, types.NewString(`/(?P<w>[a-z])\w*/`), types.NewString(""), types.NewString("go php"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ToString() != "G P" || count.ToInt() != 2 {
+		t.Errorf("Expected 'G P' with 2 replacements, got %q (%v)", result.ToString(), count)
+	}
+}
+
+// ============================================================================
+// Splitting and Quoting Tests
+// ============================================================================
+
+func TestPregSplit(t *testing.T) {
+	tests := []struct {
+		pattern, subject string
+		limit, flags     int64
+		expected         string
+	}{
+		{`/[\s,]+/`, "a, b  c,d", -1, 0, "a|b|c|d"},
+		{`/,/`, "a,b,c", 2, 0, "a|b,c"},
+		{`/,/`, ",a,,b,", -1, 0, "|a||b|"},
+		{`/,/`, ",a,,b,", -1, PREG_SPLIT_NO_EMPTY, "a|b"},
+		{`/(-)/`, "a-b", -1, PREG_SPLIT_DELIM_CAPTURE, "a|-|b"},
+		{`//`, "abc", -1, PREG_SPLIT_NO_EMPTY, "a|b|c"},
+	}
+	for _, tt := range tests {
+		result := PregSplit(types.NewString(tt.pattern), types.NewString(tt.subject), types.NewInt(tt.limit), types.NewInt(tt.flags))
+		if got := strings.Join(list(result), "|"); got != tt.expected {
+			t.Errorf("preg_split(%s, %q, %d, %d): expected %q, got %q", tt.pattern, tt.subject, tt.limit, tt.flags, tt.expected, got)
+		}
+	}
+
+	result := PregSplit(types.NewString("/ /"), types.NewString("a b"), types.NewInt(-1), types.NewInt(PREG_SPLIT_OFFSET_CAPTURE))
+	if pair := get(result, 1); get(pair, 0).ToString() != "b" || get(pair, 1).ToInt() != 2 {
+		t.Errorf("Expected [piece, offset] pairs, got %s", result.ToArray())
+	}
+}
+
+func TestPregQuote(t *testing.T) {
+	result := PregQuote(types.NewString("1.5*2 = 3? #/"), types.NewString("/"))
+	if expected := `1\.5\*2 \= 3\? \#\/`; result.ToString() != expected {
+		t.Errorf("Expected %q, got %q", expected, result.ToString())
+	}
+
+	// A quoted string matches itself
+	quoted := PregQuote(types.NewString("a+b(c)"))
+	if r, _ := PregMatch(types.NewString("/^"+quoted.ToString()+"$/"), types.NewString("a+b(c)")); r.ToInt() != 1 {
+		t.Errorf("Expected the quoted pattern to match literally, got %v", r)
+	}
+}
+
+// ============================================================================
+// Error Tests
+// ============================================================================
+
+func TestPregLastError(t *testing.T) {
+	PregMatch(types.NewString("/a/u"), types.NewString("\xff"))
+	if PregLastError().ToInt() != PREG_BAD_UTF8_ERROR {
+		t.Errorf("Expected PREG_BAD_UTF8_ERROR, got %v", PregLastError())
+	}
+	if PregLastErrorMsg().ToString() != "Malformed UTF-8 characters, possibly incorrectly encoded" {
+		t.Errorf("Unexpected message %q", PregLastErrorMsg().ToString())
+	}
+
+	PregMatch(types.NewString("/a/"), types.NewString("a"))
+	if PregLastError().ToInt() != PREG_NO_ERROR || PregLastErrorMsg().ToString() != "No error" {
+		t.Errorf("Expected the error to be reset, got %v", PregLastError())
+	}
+}
@@ -0,0 +1,354 @@
+package pcre
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ============================================================================
+// Pattern Compilation
+// ============================================================================
+
+// Regex is a compiled PHP regular expression ("/pattern/flags")
+//
+// PHP patterns are PCRE; they are translated to Go's RE2 syntax, which
+// guarantees linear time matching but lacks some PCRE features. Patterns
+// using backreferences, lookaround assertions, atomic groups, recursion or
+// \G fail to compile. Possessive quantifiers are treated as greedy ones,
+// and $ without the m modifier only matches at the very end of the subject
+// (as with the D modifier).
+type Regex struct {
+	re       *regexp.Regexp
+	utf8     bool     // u modifier: subject and pattern are UTF-8
+	anchored bool     // A modifier: match only at the start offset
+	names    []string // Group names by group index ("" for unnamed groups)
+}
+
+// NumGroups returns the number of capture groups, excluding the whole match
+func (r *Regex) NumGroups() int {
+	return r.re.NumSubexp()
+}
+
+// compiled patterns, keyed by the PHP pattern string (like PCRE's cache)
+var (
+	cacheMu sync.RWMutex
+	cache   = make(map[string]*Regex)
+)
+
+// Compile parses a PHP regular expression with its delimiters and
+// modifiers. Compiled patterns are cached.
+func Compile(pattern string) (*Regex, error) {
+	cacheMu.RLock()
+	r, ok := cache[pattern]
+	cacheMu.RUnlock()
+	if ok {
+		return r, nil
+	}
+
+	r, err := compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheMu.Lock()
+	cache[pattern] = r
+	cacheMu.Unlock()
+	return r, nil
+}
+
+// compile parses and translates a pattern without consulting the cache
+func compile(pattern string) (*Regex, error) {
+	body, modifiers, err := splitDelimiters(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Regex{}
+	var flags string
+	extended := false
+	for _, m := range modifiers {
+		switch m {
+		case 'i', 'm', 's', 'U':
+			if !strings.ContainsRune(flags, m) {
+				flags += string(m)
+			}
+		case 'x':
+			extended = true
+		case 'u':
+			r.utf8 = true
+		case 'A':
+			r.anchored = true
+		case 'D', 'S', 'X', 'J':
+			// D is RE2's behavior, the others have no effect here
+		case '\n', '\r', ' ':
+			// Whitespace after the closing delimiter is ignored
+		default:
+			return nil, fmt.Errorf("Unknown modifier '%c'", m)
+		}
+	}
+
+	translated, err := translate(body, extended)
+	if err != nil {
+		return nil, err
+	}
+	if r.anchored {
+		translated = `\A(?:` + translated + `)`
+	}
+	if flags != "" {
+		translated = "(?" + flags + ")" + translated
+	}
+
+	re, err := regexp.Compile(translated)
+	if err != nil {
+		return nil, fmt.Errorf("Compilation failed: %v", err)
+	}
+	r.re = re
+	r.names = re.SubexpNames()
+	return r, nil
+}
+
+// splitDelimiters separates the pattern body from its delimiters and modifiers
+func splitDelimiters(pattern string) (string, string, error) {
+	p := strings.TrimLeft(pattern, " \t\n\r\v\f")
+	if p == "" {
+		return "", "", fmt.Errorf("Empty regular expression")
+	}
+
+	start := p[0]
+	if isAlnum(start) || start == '\\' {
+		return "", "", fmt.Errorf("Delimiter must not be alphanumeric, backslash, or NUL")
+	}
+
+	end := start
+	switch start {
+	case '(':
+		end = ')'
+	case '[':
+		end = ']'
+	case '{':
+		end = '}'
+	case '<':
+		end = '>'
+	}
+
+	// Bracket-style delimiters nest; escaped delimiters do not count
+	depth := 0
+	for i := 1; i < len(p); i++ {
+		switch {
+		case p[i] == '\\':
+			i++
+		case p[i] == end && depth == 0:
+			return p[1:i], p[i+1:], nil
+		case p[i] == end:
+			depth--
+		case p[i] == start && start != end:
+			depth++
+		}
+	}
+
+	if start != end {
+		return "", "", fmt.Errorf("No ending matching delimiter '%c' found", end)
+	}
+	return "", "", fmt.Errorf("No ending delimiter '%c' found", end)
+}
+
+// isAlnum reports whether c is an ASCII letter or digit
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// Escapes PCRE supports and RE2 does not, as RE2 syntax usable both inside
+// and outside character classes
+var classEscapes = map[byte]string{
+	'h': `\t \x{A0}\x{1680}\x{180E}\x{2000}-\x{200A}\x{202F}\x{205F}\x{3000}`,
+	'v': `\n\x0B\f\r\x{85}\x{2028}\x{2029}`,
+}
+
+// translate rewrites a PCRE pattern body into RE2 syntax
+func translate(body string, extended bool) (string, error) {
+	var out strings.Builder
+	inClass := false
+
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+
+		switch {
+		case c == '\\':
+			if i+1 >= len(body) {
+				return "", fmt.Errorf("Compilation failed: \\ at end of pattern")
+			}
+			i++
+			escaped, err := translateEscape(body, i, inClass)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(escaped)
+			// \Q...\E is copied verbatim
+			if body[i] == 'Q' {
+				if endQ := strings.Index(body[i+1:], `\E`); endQ >= 0 {
+					out.WriteString(body[i+1 : i+1+endQ+2])
+					i += endQ + 2
+				} else {
+					out.WriteString(body[i+1:])
+					i = len(body)
+				}
+			}
+
+		case inClass:
+			if c == '[' && i+1 < len(body) && body[i+1] == ':' {
+				// POSIX class such as [:alpha:]
+				if end := strings.Index(body[i:], ":]"); end >= 0 {
+					out.WriteString(body[i : i+end+2])
+					i += end + 1
+					continue
+				}
+			}
+			if c == ']' {
+				inClass = false
+			}
+			out.WriteByte(c)
+
+		case c == '[':
+			inClass = true
+			out.WriteByte(c)
+			// A ] right after [ or [^ is a literal
+			if i+1 < len(body) && body[i+1] == '^' {
+				out.WriteByte('^')
+				i++
+			}
+			if i+1 < len(body) && body[i+1] == ']' {
+				out.WriteString(`\]`)
+				i++
+			}
+
+		case extended && (c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'):
+			// x modifier: whitespace outside classes is ignored
+
+		case extended && c == '#':
+			// x modifier: comments run to the end of the line
+			for i+1 < len(body) && body[i+1] != '\n' {
+				i++
+			}
+
+		case c == '(' && i+1 < len(body) && body[i+1] == '?':
+			group, skip, err := translateGroup(body[i:])
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(group)
+			i += skip - 1
+
+		case (c == '+') && i > 0 && isQuantifierEnd(body, i-1):
+			// Possessive quantifier: matched as a greedy one
+
+		default:
+			out.WriteByte(c)
+		}
+	}
+
+	return out.String(), nil
+}
+
+// isQuantifierEnd reports whether the character at i ends a quantifier
+// (*, +, ?, {n,m}) that is not escaped
+func isQuantifierEnd(body string, i int) bool {
+	switch body[i] {
+	case '*', '+', '?', '}':
+	default:
+		return false
+	}
+	backslashes := 0
+	for j := i - 1; j >= 0 && body[j] == '\\'; j-- {
+		backslashes++
+	}
+	if backslashes%2 == 1 {
+		return false
+	}
+	// "x++" is possessive, but the second + of "x+++" is not a quantifier end
+	if body[i] == '+' && i > 0 && isQuantifierEnd(body, i-1) {
+		return false
+	}
+	return true
+}
+
+// translateEscape rewrites the escape sequence whose letter is at body[i]
+func translateEscape(body string, i int, inClass bool) (string, error) {
+	c := body[i]
+
+	if chars, ok := classEscapes[c]; ok {
+		if inClass {
+			return chars, nil
+		}
+		return "[" + chars + "]", nil
+	}
+
+	switch c {
+	case 'H', 'V':
+		chars := classEscapes[c+'a'-'A']
+		if inClass {
+			return "", fmt.Errorf("Compilation failed: \\%c is not supported in a character class", c)
+		}
+		return "[^" + chars + "]", nil
+	case 'R':
+		return `(?:\r\n|[\n\x0B\f\r\x{85}\x{2028}\x{2029}])`, nil
+	case 'e':
+		return `\x1B`, nil
+	case 'Z':
+		return `\z`, nil
+	case 'G', 'K', 'X', 'g', 'k':
+		return "", fmt.Errorf("Compilation failed: \\%c is not supported", c)
+	case 'Q':
+		// The caller copies the quoted text up to \E
+		return `\Q`, nil
+	}
+
+	if c >= '1' && c <= '9' && !inClass {
+		return "", fmt.Errorf("Compilation failed: backreferences are not supported")
+	}
+	return `\` + string(c), nil
+}
+
+// translateGroup rewrites a group starting with "(?" and returns the
+// replacement and the number of bytes consumed
+func translateGroup(s string) (string, int, error) {
+	switch {
+	case strings.HasPrefix(s, "(?#"):
+		// Comment
+		end := strings.IndexByte(s, ')')
+		if end < 0 {
+			return "", 0, fmt.Errorf("Compilation failed: missing ) after (?# comment")
+		}
+		return "", end + 1, nil
+
+	case strings.HasPrefix(s, "(?'"):
+		// (?'name'...) named group
+		end := strings.IndexByte(s[3:], '\'')
+		if end < 0 {
+			return "", 0, fmt.Errorf("Compilation failed: syntax error in subpattern name (missing terminator?)")
+		}
+		return "(?P<" + s[3:3+end] + ">", 3 + end + 1, nil
+
+	case strings.HasPrefix(s, "(?<=") || strings.HasPrefix(s, "(?<!") ||
+		strings.HasPrefix(s, "(?=") || strings.HasPrefix(s, "(?!"):
+		return "", 0, fmt.Errorf("Compilation failed: lookaround assertions are not supported")
+
+	case strings.HasPrefix(s, "(?>"):
+		return "", 0, fmt.Errorf("Compilation failed: atomic groups are not supported")
+
+	case strings.HasPrefix(s, "(?|"):
+		return "", 0, fmt.Errorf("Compilation failed: branch reset groups are not supported")
+
+	case strings.HasPrefix(s, "(?P=") || strings.HasPrefix(s, "(?P>") || strings.HasPrefix(s, "(?R") ||
+		strings.HasPrefix(s, "(?&") || len(s) > 2 && (s[2] >= '0' && s[2] <= '9' || s[2] == '+' || s[2] == '-' && len(s) > 3 && s[3] >= '0' && s[3] <= '9'):
+		return "", 0, fmt.Errorf("Compilation failed: recursion and backreferences are not supported")
+	}
+
+	// Inline options: (?x) and (?-x) are handled by translate only as modifiers
+	if end := strings.IndexAny(s[2:], ":)"); end >= 0 && strings.ContainsRune(s[2:2+end], 'x') && !strings.HasPrefix(s, "(?P<") && !strings.HasPrefix(s, "(?<") {
+		return "", 0, fmt.Errorf("Compilation failed: inline x option is not supported")
+	}
+
+	return "(?", 2, nil
+}
@@ -0,0 +1,90 @@
+package pcre
+
+import "testing"
+
+// ============================================================================
+// Compile Tests
+// ============================================================================
+
+func TestCompileDelimiters(t *testing.T) {
+	tests := []struct {
+		pattern string
+		subject string
+		match   bool
+	}{
+		{"/abc/", "xabcx", true},
+		{"#a/b#", "a/b", true},
+		{"{a{1,2}}", "aa", true},
+		{"(a(b))", "ab", true},
+		{"[a]", "a", true},
+		{"<a>", "a", true},
+		{`/a\/b/`, "a/b", true},
+		{"  /abc/", "abc", true},
+		{"/ABC/i", "abc", true},
+		{"/^b$/m", "a\nb\nc", true},
+		{"/a.b/s", "a\nb", true},
+		{"/a.b/", "a\nb", false},
+		{"/a b # comment\n c/x", "abc", true},
+		{"/b/A", "ab", false},
+		{"/a+?/U", "aaa", true},
+	}
+	for _, tt := range tests {
+		r, err := Compile(tt.pattern)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.pattern, err)
+			continue
+		}
+		if got := r.find(tt.subject, 0) != nil; got != tt.match {
+			t.Errorf("%q on %q: expected match=%v", tt.pattern, tt.subject, tt.match)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, pattern := range []string{
+		"",
+		"abc",
+		`\abc\`,
+		"/abc",
+		"(abc",
+		"/abc/k",
+		`/(a)\1/`,
+		"/a(?=b)/",
+		"/(?>a)/",
+		`/\Ga/`,
+		"/a(/",
+	} {
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("Compile(%q): expected an error", pattern)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		body     string
+		extended bool
+		expected string
+	}{
+		{`a++b`, false, `a+b`},
+		{`a\++`, false, `a\++`},
+		{`[]a]`, false, `[\]a]`},
+		{`[^]a]`, false, `[^\]a]`},
+		{`[[:alpha:]]`, false, `[[:alpha:]]`},
+		{`\Qa.b\E`, false, `\Qa.b\E`},
+		{`(?'word'\w+)`, false, `(?P<word>\w+)`},
+		{`a(?# comment )b`, false, `ab`},
+		{`a\Z`, false, `a\z`},
+		{`[ a ] b # c`, true, `[ a ]b`},
+	}
+	for _, tt := range tests {
+		got, err := translate(tt.body, tt.extended)
+		if err != nil {
+			t.Errorf("translate(%q): %v", tt.body, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("translate(%q): expected %q, got %q", tt.body, tt.expected, got)
+		}
+	}
+}
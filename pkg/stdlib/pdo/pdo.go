@@ -0,0 +1,527 @@
+package pdo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// PDO Constants
+// ============================================================================
+
+// Parameter types
+const (
+	PARAM_NULL = 0
+	PARAM_INT  = 1
+	PARAM_STR  = 2
+	PARAM_LOB  = 3
+	PARAM_BOOL = 5
+)
+
+// Fetch modes
+const (
+	FETCH_DEFAULT   = 0
+	FETCH_LAZY      = 1
+	FETCH_ASSOC     = 2
+	FETCH_NUM       = 3
+	FETCH_BOTH      = 4
+	FETCH_OBJ       = 5
+	FETCH_COLUMN    = 7
+	FETCH_CLASS     = 8
+	FETCH_KEY_PAIR  = 12
+	FETCH_GROUP     = 65536
+	FETCH_UNIQUE    = 196608
+	FETCH_ORI_NEXT  = 0
+	FETCH_ORI_FIRST = 2
+)
+
+// Attributes
+const (
+	ATTR_AUTOCOMMIT         = 0
+	ATTR_ERRMODE            = 3
+	ATTR_CASE               = 8
+	ATTR_DRIVER_NAME        = 16
+	ATTR_STRINGIFY_FETCHES  = 17
+	ATTR_DEFAULT_FETCH_MODE = 19
+	ATTR_EMULATE_PREPARES   = 20
+)
+
+// Error modes
+const (
+	ERRMODE_SILENT    = 0
+	ERRMODE_WARNING   = 1
+	ERRMODE_EXCEPTION = 2
+)
+
+// Column name case (ATTR_CASE)
+const (
+	CASE_NATURAL = 0
+	CASE_UPPER   = 1
+	CASE_LOWER   = 2
+)
+
+// ERR_NONE is the SQLSTATE of a successful operation
+const ERR_NONE = "00000"
+
+// ============================================================================
+// Errors
+// ============================================================================
+
+// Error is a PDO error: an SQLSTATE code, an optional driver error code and
+// a message. It is reported through errorInfo() and PDOException.
+type Error struct {
+	SQLState   string
+	DriverCode *int64 // nil when the driver does not report one
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("SQLSTATE[%s]: %s", e.SQLState, e.Message)
+}
+
+// Info returns the errorInfo() array: SQLSTATE, driver code, driver message
+func (e *Error) Info() *types.Value {
+	info := types.NewArrayFromSlice([]*types.Value{types.NewString(e.SQLState), types.NewNull(), types.NewNull()})
+	if e.DriverCode != nil {
+		info.Set(types.NewInt(1), types.NewInt(*e.DriverCode))
+	}
+	if e.SQLState != ERR_NONE {
+		info.Set(types.NewInt(2), types.NewString(e.Message))
+	}
+	return types.NewArray(info)
+}
+
+// noError is the error state after a successful operation
+var noError = &Error{SQLState: ERR_NONE}
+
+// driverError wraps an error returned by a database/sql driver
+func driverError(err error) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	return &Error{SQLState: "HY000", Message: "General error: " + err.Error()}
+}
+
+// ============================================================================
+// Drivers
+// ============================================================================
+
+// Driver maps a PDO driver (the DSN prefix) to a database/sql driver
+type Driver struct {
+	// database/sql driver names to use, in order of preference. The
+	// embedding program registers one by importing it; the php-go command
+	// links SQLite (github.com/mattn/go-sqlite3, as "sqlite3").
+	SQLDrivers []string
+
+	// DataSource converts the part of the DSN after the prefix, and the
+	// user name and password, into a data source name for the driver
+	DataSource func(dsn string, user, password string) string
+
+	// Placeholder returns the n-th (1-based) positional parameter; nil
+	// means "?"
+	Placeholder func(n int) string
+
+	// EscapeBackslashes makes quote() escape backslashes (MySQL)
+	EscapeBackslashes bool
+}
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]*Driver{
+		"sqlite": {
+			SQLDrivers: []string{"sqlite3", "sqlite"},
+			DataSource: func(dsn, user, password string) string { return dsn },
+		},
+		"mysql": {
+			SQLDrivers:        []string{"mysql"},
+			DataSource:        mysqlDataSource,
+			EscapeBackslashes: true,
+		},
+		"pgsql": {
+			SQLDrivers:  []string{"pgx", "postgres"},
+			DataSource:  pgsqlDataSource,
+			Placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+		},
+	}
+)
+
+// RegisterDriver registers or replaces the PDO driver for a DSN prefix
+func RegisterDriver(name string, driver *Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[name] = driver
+}
+
+// sqlDriver returns the first registered database/sql driver of a PDO driver
+func (d *Driver) sqlDriver() (string, bool) {
+	registered := sql.Drivers()
+	for _, name := range d.SQLDrivers {
+		i := sort.SearchStrings(registered, name)
+		if i < len(registered) && registered[i] == name {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// AvailableDrivers returns the PDO drivers whose database/sql driver is
+// registered, for PDO::getAvailableDrivers()
+func AvailableDrivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	var names []string
+	for name, driver := range drivers {
+		if _, ok := driver.sqlDriver(); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dsnParams parses "key=value;key=value" DSN parameters
+func dsnParams(dsn string) map[string]string {
+	params := make(map[string]string)
+	for _, part := range strings.Split(dsn, ";") {
+		if key, value, ok := strings.Cut(part, "="); ok {
+			params[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return params
+}
+
+// mysqlDataSource builds a go-sql-driver/mysql DSN:
+// user:password@tcp(host:port)/dbname?charset=...
+func mysqlDataSource(dsn, user, password string) string {
+	params := dsnParams(dsn)
+	if user == "" {
+		user = params["user"]
+	}
+	if password == "" {
+		password = params["password"]
+	}
+
+	address := "tcp(" + net.JoinHostPort(orDefault(params["host"], "localhost"), orDefault(params["port"], "3306")) + ")"
+	if socket := params["unix_socket"]; socket != "" {
+		address = "unix(" + socket + ")"
+	}
+
+	var out strings.Builder
+	if user != "" {
+		out.WriteString(user)
+		if password != "" {
+			out.WriteString(":" + password)
+		}
+		out.WriteString("@")
+	}
+	out.WriteString(address + "/" + params["dbname"])
+	if charset := params["charset"]; charset != "" {
+		out.WriteString("?charset=" + charset)
+	}
+	return out.String()
+}
+
+// pgsqlDataSource builds a keyword/value connection string accepted by the
+// PostgreSQL drivers: host=... port=... dbname=... user=... password=...
+func pgsqlDataSource(dsn, user, password string) string {
+	params := dsnParams(dsn)
+	if user != "" {
+		params["user"] = user
+	}
+	if password != "" {
+		params["password"] = password
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(params[key])
+		parts = append(parts, key+"='"+value+"'")
+	}
+	return strings.Join(parts, " ")
+}
+
+// orDefault returns s, or def if s is empty
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// ============================================================================
+// Connections
+// ============================================================================
+
+// queryer runs statements on the database or the current transaction
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Conn is a database connection, the payload of PDO objects
+type Conn struct {
+	driverName string
+	driver     *Driver
+	db         *sql.DB
+	tx         *sql.Tx
+
+	errMode          int
+	defaultFetchMode int
+	stringify        bool
+	columnCase       int
+	attributes       map[int]*types.Value // Other attributes, stored as set
+
+	lastInsertID int64
+	err          *Error
+}
+
+// Open connects to the database described by a PDO DSN ("driver:params")
+func Open(dsn, user, password string) (*Conn, error) {
+	prefix, rest, ok := strings.Cut(dsn, ":")
+	if !ok {
+		return nil, &Error{SQLState: "IM001", Message: "invalid data source name"}
+	}
+
+	driversMu.RLock()
+	driver, ok := drivers[prefix]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, &Error{SQLState: "IM001", Message: "could not find driver"}
+	}
+	sqlDriver, ok := driver.sqlDriver()
+	if !ok {
+		return nil, &Error{SQLState: "IM001", Message: "could not find driver"}
+	}
+
+	db, err := sql.Open(sqlDriver, driver.DataSource(rest, user, password))
+	if err != nil {
+		return nil, driverError(err)
+	}
+	// A PDO object is a single session: temporary tables, session variables
+	// and last insert IDs belong to one connection
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, driverError(err)
+	}
+
+	return &Conn{
+		driverName:       prefix,
+		driver:           driver,
+		db:               db,
+		errMode:          ERRMODE_EXCEPTION,
+		defaultFetchMode: FETCH_BOTH,
+		attributes:       make(map[int]*types.Value),
+		err:              noError,
+	}, nil
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	if c.tx != nil {
+		c.tx.Rollback()
+		c.tx = nil
+	}
+	return c.db.Close()
+}
+
+// ErrMode returns the error mode (ATTR_ERRMODE)
+func (c *Conn) ErrMode() int {
+	return c.errMode
+}
+
+// LastError returns the error state of the last operation on the connection
+func (c *Conn) LastError() *Error {
+	return c.err
+}
+
+// fail records an error and returns it
+func (c *Conn) fail(err error) *Error {
+	c.err = driverError(err)
+	return c.err
+}
+
+// queryer returns the transaction, if one is active, or the database
+func (c *Conn) queryer() queryer {
+	if c.tx != nil {
+		return c.tx
+	}
+	return c.db
+}
+
+// SetAttribute sets a connection attribute
+func (c *Conn) SetAttribute(attribute int, value *types.Value) error {
+	switch attribute {
+	case ATTR_ERRMODE:
+		mode := int(value.ToInt())
+		if mode < ERRMODE_SILENT || mode > ERRMODE_EXCEPTION {
+			return fmt.Errorf("Error mode must be one of the PDO::ERRMODE_* constants")
+		}
+		c.errMode = mode
+	case ATTR_DEFAULT_FETCH_MODE:
+		c.defaultFetchMode = int(value.ToInt())
+	case ATTR_STRINGIFY_FETCHES:
+		c.stringify = value.ToBool()
+	case ATTR_CASE:
+		c.columnCase = int(value.ToInt())
+	case ATTR_DRIVER_NAME:
+		return fmt.Errorf("Attribute is read-only")
+	default:
+		c.attributes[attribute] = value
+	}
+	return nil
+}
+
+// Attribute returns a connection attribute, or null if it is not set
+func (c *Conn) Attribute(attribute int) *types.Value {
+	switch attribute {
+	case ATTR_ERRMODE:
+		return types.NewInt(int64(c.errMode))
+	case ATTR_DEFAULT_FETCH_MODE:
+		return types.NewInt(int64(c.defaultFetchMode))
+	case ATTR_STRINGIFY_FETCHES:
+		return types.NewBool(c.stringify)
+	case ATTR_CASE:
+		return types.NewInt(int64(c.columnCase))
+	case ATTR_DRIVER_NAME:
+		return types.NewString(c.driverName)
+	case ATTR_AUTOCOMMIT:
+		if value, ok := c.attributes[attribute]; ok {
+			return value
+		}
+		return types.NewBool(c.tx == nil)
+	}
+	if value, ok := c.attributes[attribute]; ok {
+		return value
+	}
+	return types.NewNull()
+}
+
+// Exec runs a statement and returns the number of affected rows
+func (c *Conn) Exec(query string) (int64, error) {
+	result, err := c.queryer().ExecContext(context.Background(), query)
+	if err != nil {
+		return 0, c.fail(err)
+	}
+	c.recordInsertID(result)
+	c.err = noError
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, nil
+	}
+	return affected, nil
+}
+
+// recordInsertID remembers the ID generated by an INSERT, if the driver
+// reports one
+func (c *Conn) recordInsertID(result sql.Result) {
+	if id, err := result.LastInsertId(); err == nil && id != 0 {
+		c.lastInsertID = id
+	}
+}
+
+// LastInsertID returns the ID of the last inserted row
+func (c *Conn) LastInsertID() string {
+	return fmt.Sprint(c.lastInsertID)
+}
+
+// Prepare prepares a statement for execution. Named (:name) and positional
+// (?) parameters are rewritten into the driver's placeholder style.
+func (c *Conn) Prepare(query string) (*Statement, error) {
+	rewritten, params, err := rewriteQuery(query, c.driver.Placeholder)
+	if err != nil {
+		return nil, c.fail(err)
+	}
+	c.err = noError
+	return &Statement{
+		conn:      c,
+		query:     query,
+		rewritten: rewritten,
+		params:    params,
+		bound:     make(map[string]binding),
+		fetchMode: FETCH_DEFAULT,
+		err:       noError,
+	}, nil
+}
+
+// Query prepares and executes a statement without parameters
+func (c *Conn) Query(query string) (*Statement, error) {
+	stmt, err := c.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	if err := stmt.Execute(nil); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// Begin starts a transaction, turning off autocommit
+func (c *Conn) Begin() error {
+	if c.tx != nil {
+		return c.fail(&Error{SQLState: "HY000", Message: "There is already an active transaction"})
+	}
+	tx, err := c.db.Begin()
+	if err != nil {
+		return c.fail(err)
+	}
+	c.tx = tx
+	c.err = noError
+	return nil
+}
+
+// Commit commits the active transaction
+func (c *Conn) Commit() error {
+	if c.tx == nil {
+		return c.fail(&Error{SQLState: "HY000", Message: "There is no active transaction"})
+	}
+	err := c.tx.Commit()
+	c.tx = nil
+	if err != nil {
+		return c.fail(err)
+	}
+	c.err = noError
+	return nil
+}
+
+// Rollback rolls back the active transaction
+func (c *Conn) Rollback() error {
+	if c.tx == nil {
+		return c.fail(&Error{SQLState: "HY000", Message: "There is no active transaction"})
+	}
+	err := c.tx.Rollback()
+	c.tx = nil
+	if err != nil {
+		return c.fail(err)
+	}
+	c.err = noError
+	return nil
+}
+
+// InTransaction reports whether a transaction is active
+func (c *Conn) InTransaction() bool {
+	return c.tx != nil
+}
+
+// Quote quotes a string for use in a query
+func (c *Conn) Quote(s string) string {
+	if c.driver.EscapeBackslashes {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,265 @@
+package pdo
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Fake Driver
+// ============================================================================
+
+// fakeDB is an in-memory database for the "pdotest" driver. Queries
+// return the canned result registered for their text; every statement
+// and transaction event is logged.
+type fakeDB struct {
+	mu      sync.Mutex
+	results map[string]fakeResult
+	log     []string
+	lastID  int64
+}
+
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+var (
+	fakeMu  sync.Mutex
+	fakeDBs = make(map[string]*fakeDB)
+)
+
+func init() {
+	sql.Register("pdotest", fakeDriver{})
+	RegisterDriver("fake", &Driver{
+		SQLDrivers: []string{"pdotest"},
+		DataSource: func(dsn, user, password string) string { return dsn },
+	})
+}
+
+// newFakeDB creates the database behind the DSN "fake:<name>"
+func newFakeDB(t *testing.T) (*fakeDB, string) {
+	db := &fakeDB{results: make(map[string]fakeResult)}
+	fakeMu.Lock()
+	fakeDBs[t.Name()] = db
+	fakeMu.Unlock()
+	return db, "fake:" + t.Name()
+}
+
+func (db *fakeDB) record(format string, args ...any) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.log = append(db.log, fmt.Sprintf(format, args...))
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeMu.Lock()
+	defer fakeMu.Unlock()
+	db, ok := fakeDBs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown database %q", name)
+	}
+	return &fakeConn{db: db}, nil
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.Contains(query, "syntax error") {
+		return nil, errors.New(`near "error": syntax error`)
+	}
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.record("BEGIN")
+	return &fakeTx{db: c.db}, nil
+}
+
+type fakeTx struct{ db *fakeDB }
+
+func (tx *fakeTx) Commit() error   { tx.db.record("COMMIT"); return nil }
+func (tx *fakeTx) Rollback() error { tx.db.record("ROLLBACK"); return nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.record("%s %v", s.query, args)
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	if strings.HasPrefix(s.query, "INSERT") {
+		s.db.lastID++
+		return fakeExecResult{affected: 1, id: s.db.lastID}, nil
+	}
+	return driver.RowsAffected(3), nil
+}
+
+type fakeExecResult struct{ affected, id int64 }
+
+func (r fakeExecResult) LastInsertId() (int64, error) { return r.id, nil }
+func (r fakeExecResult) RowsAffected() (int64, error) { return r.affected, nil }
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.record("%s %v", s.query, args)
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	result, ok := s.db.results[s.query]
+	if !ok {
+		return nil, fmt.Errorf("no such table")
+	}
+	return &fakeRows{result: result}, nil
+}
+
+type fakeRows struct {
+	result fakeResult
+	next   int
+}
+
+func (r *fakeRows) Columns() []string { return r.result.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.next])
+	r.next++
+	return nil
+}
+
+// openFake connects to a new fake database
+func openFake(t *testing.T) (*fakeDB, *Conn) {
+	t.Helper()
+	db, dsn := newFakeDB(t)
+	conn, err := Open(dsn, "", "")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return db, conn
+}
+
+// ============================================================================
+// Connection Tests
+// ============================================================================
+
+func TestOpenUnknownDriver(t *testing.T) {
+	for _, dsn := range []string{"nodsn", "oracle:db", "sqlite::memory:"} {
+		_, err := Open(dsn, "", "")
+		var pdoErr *Error
+		if !errors.As(err, &pdoErr) || pdoErr.SQLState != "IM001" {
+			t.Errorf("Open(%q): expected an IM001 error, got %v", dsn, err)
+		}
+	}
+}
+
+func TestAvailableDrivers(t *testing.T) {
+	if got := strings.Join(AvailableDrivers(), ","); got != "fake" {
+		t.Errorf("Expected only the fake driver to be available, got %q", got)
+	}
+}
+
+func TestDataSources(t *testing.T) {
+	mysql := mysqlDataSource("host=db;port=3307;dbname=app;charset=utf8mb4", "root", "secret")
+	if mysql != "root:secret@tcp(db:3307)/app?charset=utf8mb4" {
+		t.Errorf("Unexpected MySQL data source %q", mysql)
+	}
+	if socket := mysqlDataSource("unix_socket=/tmp/mysql.sock;dbname=app", "", ""); socket != "unix(/tmp/mysql.sock)/app" {
+		t.Errorf("Unexpected MySQL socket data source %q", socket)
+	}
+
+	pgsql := pgsqlDataSource("host=localhost;dbname=app", "admin", "it's")
+	if pgsql != `dbname='app' host='localhost' password='it\'s' user='admin'` {
+		t.Errorf("Unexpected PostgreSQL data source %q", pgsql)
+	}
+}
+
+func TestConnExecAndTransactions(t *testing.T) {
+	db, conn := openFake(t)
+
+	if err := conn.Begin(); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if !conn.InTransaction() {
+		t.Error("Expected a transaction to be active")
+	}
+	if err := conn.Begin(); err == nil {
+		t.Error("Expected nested Begin to fail")
+	}
+
+	affected, err := conn.Exec("UPDATE t SET a = 1")
+	if err != nil || affected != 3 {
+		t.Errorf("Expected 3 affected rows, got %d (%v)", affected, err)
+	}
+	if _, err := conn.Exec("INSERT INTO t VALUES (1)"); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if err := conn.Rollback(); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if err := conn.Commit(); err == nil {
+		t.Error("Expected Commit without a transaction to fail")
+	}
+
+	if got := strings.Join(db.log, "; "); got != "BEGIN; UPDATE t SET a = 1 []; INSERT INTO t VALUES (1) []; ROLLBACK" {
+		t.Errorf("Unexpected statements: %s", got)
+	}
+}
+
+func TestConnErrors(t *testing.T) {
+	_, conn := openFake(t)
+
+	_, err := conn.Exec("syntax error")
+	var pdoErr *Error
+	if !errors.As(err, &pdoErr) || pdoErr.SQLState != "HY000" {
+		t.Fatalf("Expected a general error, got %v", err)
+	}
+	if !strings.Contains(pdoErr.Error(), `SQLSTATE[HY000]: General error: near "error": syntax error`) {
+		t.Errorf("Unexpected message %q", pdoErr.Error())
+	}
+	if conn.LastError() != pdoErr {
+		t.Error("Expected the connection to record the error")
+	}
+
+	info := pdoErr.Info().ToArray()
+	if state, _ := info.Get(types.NewInt(0)); state.ToString() != "HY000" {
+		t.Errorf("Expected errorInfo()[0] to be HY000, got %s", info)
+	}
+
+	if err := conn.SetAttribute(ATTR_ERRMODE, types.NewInt(7)); err == nil {
+		t.Error("Expected an invalid error mode to be rejected")
+	}
+	conn.SetAttribute(ATTR_ERRMODE, types.NewInt(ERRMODE_SILENT))
+	if conn.ErrMode() != ERRMODE_SILENT || conn.Attribute(ATTR_DRIVER_NAME).ToString() != "fake" {
+		t.Errorf("Unexpected attributes: errmode %d, driver %v", conn.ErrMode(), conn.Attribute(ATTR_DRIVER_NAME))
+	}
+}
+
+func TestConnQuote(t *testing.T) {
+	_, conn := openFake(t)
+	if got := conn.Quote(`it's \n`); got != `'it''s \n'` {
+		t.Errorf("Unexpected quoting %s", got)
+	}
+	conn.driver = drivers["mysql"]
+	if got := conn.Quote(`it's \n`); got != `'it''s \\n'` {
+		t.Errorf("Unexpected MySQL quoting %s", got)
+	}
+}
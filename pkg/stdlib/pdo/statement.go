@@ -0,0 +1,529 @@
+package pdo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Query Parameters
+// ============================================================================
+
+// rewriteQuery replaces the named (:name) and positional (?) parameters of
+// a query with the driver's placeholders. It returns the rewritten query
+// and the parameter of each placeholder: ":name", or "?" for positional
+// ones. String literals, quoted identifiers and comments are left alone.
+func rewriteQuery(query string, placeholder func(n int) string) (string, []string, error) {
+	var out strings.Builder
+	var params []string
+	named, positional := false, false
+
+	emit := func(param string) {
+		params = append(params, param)
+		if placeholder != nil {
+			out.WriteString(placeholder(len(params)))
+		} else {
+			out.WriteByte('?')
+		}
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := quotedEnd(query, i)
+			out.WriteString(query[i:end])
+			i = end - 1
+
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			out.WriteString(query[i : i+end])
+			i += end - 1
+
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i
+			} else {
+				end += 4
+			}
+			out.WriteString(query[i : i+end])
+			i += end - 1
+
+		case c == '?':
+			positional = true
+			emit("?")
+
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			// PostgreSQL cast (value::type)
+			out.WriteString("::")
+			i++
+
+		case c == ':' && i+1 < len(query) && isParamChar(query[i+1]):
+			end := i + 1
+			for end < len(query) && isParamChar(query[end]) {
+				end++
+			}
+			named = true
+			emit(query[i:end])
+			i = end - 1
+
+		default:
+			out.WriteByte(c)
+		}
+	}
+
+	if named && positional {
+		return "", nil, &Error{SQLState: "HY093", Message: "Invalid parameter number: mixed named and positional parameters"}
+	}
+	return out.String(), params, nil
+}
+
+// quotedEnd returns the index after the quoted string starting at i;
+// doubled quotes and backslash escapes do not end it
+func quotedEnd(query string, i int) int {
+	quote := query[i]
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			if quote != '`' {
+				j++
+			}
+		case quote:
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(query)
+}
+
+// isParamChar reports whether c can appear in a parameter name
+func isParamChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// binding is a value bound to a parameter, with its PARAM_* type. Values
+// bound with bindParam() are references, read when the statement runs.
+type binding struct {
+	value *types.Value
+	typ   int
+}
+
+// driverArg converts a PHP value to a database/sql argument
+func driverArg(value *types.Value, typ int) any {
+	value = value.Deref()
+	if value.IsNull() || typ == PARAM_NULL {
+		return nil
+	}
+
+	switch typ {
+	case PARAM_INT:
+		return value.ToInt()
+	case PARAM_BOOL:
+		return value.ToBool()
+	}
+
+	switch value.Type() {
+	case types.TypeInt:
+		return value.ToInt()
+	case types.TypeFloat:
+		return value.ToFloat()
+	case types.TypeBool:
+		if value.ToBool() {
+			return int64(1)
+		}
+		return int64(0)
+	default:
+		return value.ToString()
+	}
+}
+
+// ============================================================================
+// Statements
+// ============================================================================
+
+// Statement is a prepared statement and, once executed, its result set.
+// Results are read completely when the statement runs, like PDO's buffered
+// queries, so the connection is free for other statements.
+type Statement struct {
+	conn      *Conn
+	query     string
+	rewritten string
+	params    []string
+	bound     map[string]binding
+
+	fetchMode int
+	fetchArg  *types.Value // Column index for FETCH_COLUMN
+
+	columns  []string
+	rows     [][]any
+	cursor   int
+	rowCount int64
+	err      *Error
+}
+
+// QueryString returns the query the statement was prepared from
+func (s *Statement) QueryString() string {
+	return s.query
+}
+
+// LastError returns the error state of the last operation on the statement
+func (s *Statement) LastError() *Error {
+	return s.err
+}
+
+// Conn returns the connection the statement belongs to
+func (s *Statement) Conn() *Conn {
+	return s.conn
+}
+
+// fail records an error on the statement and the connection
+func (s *Statement) fail(err error) *Error {
+	s.err = s.conn.fail(err)
+	return s.err
+}
+
+// paramKey returns the binding key of a parameter: ":name" for named
+// parameters (the colon is optional) or the 1-based position
+func paramKey(param *types.Value) (string, error) {
+	if param.IsInt() {
+		if param.ToInt() < 1 {
+			return "", &Error{SQLState: "HY093", Message: "Invalid parameter number: Columns/Parameters are 1-based"}
+		}
+		return strconv.FormatInt(param.ToInt(), 10), nil
+	}
+	name := param.ToString()
+	if !strings.HasPrefix(name, ":") {
+		name = ":" + name
+	}
+	return name, nil
+}
+
+// Bind binds a value to a parameter. A reference is read when the
+// statement is executed (bindParam), other values are used as bound.
+func (s *Statement) Bind(param, value *types.Value, typ int) error {
+	key, err := paramKey(param)
+	if err != nil {
+		return s.fail(err)
+	}
+	if strings.HasPrefix(key, ":") && !s.hasParam(key) {
+		return s.fail(&Error{SQLState: "HY093", Message: "Invalid parameter number: parameter was not defined"})
+	}
+	s.bound[key] = binding{value: value, typ: typ}
+	s.err = noError
+	return nil
+}
+
+// hasParam reports whether the query uses a named parameter
+func (s *Statement) hasParam(name string) bool {
+	for _, p := range s.params {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// args resolves the arguments of the placeholders from the execute()
+// input array, or from the bound values if input is nil
+func (s *Statement) args(input *types.Array) ([]any, error) {
+	undefined := &Error{SQLState: "HY093", Message: "Invalid parameter number: parameter was not defined"}
+
+	args := make([]any, len(s.params))
+	position := 0
+	for i, param := range s.params {
+		key := param
+		if param == "?" {
+			position++
+			key = strconv.Itoa(position)
+		}
+
+		if input == nil {
+			b, ok := s.bound[key]
+			if !ok {
+				return nil, undefined
+			}
+			args[i] = driverArg(b.value, b.typ)
+			continue
+		}
+
+		// execute() values are bound as PARAM_STR; positional ones are a list
+		var value *types.Value
+		var ok bool
+		if param == "?" {
+			value, ok = input.Get(types.NewInt(int64(position - 1)))
+		} else if value, ok = input.Get(types.NewString(param)); !ok {
+			value, ok = input.Get(types.NewString(param[1:]))
+		}
+		if !ok {
+			return nil, undefined
+		}
+		args[i] = driverArg(value, PARAM_STR)
+	}
+
+	if input != nil && position > 0 && input.Len() != position {
+		return nil, &Error{SQLState: "HY093", Message: "Invalid parameter number: number of bound variables does not match number of tokens"}
+	}
+	return args, nil
+}
+
+// returnsRows reports whether a statement produces a result set
+func returnsRows(query string) bool {
+	fields := strings.Fields(strings.TrimLeft(query, "( \t\r\n"))
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "WITH", "SHOW", "PRAGMA", "EXPLAIN", "DESCRIBE", "DESC", "VALUES", "TABLE":
+		return true
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field, "RETURNING") {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute runs the statement with the bound values, or with the values of
+// input (an array of parameter values) if it is not nil
+func (s *Statement) Execute(input *types.Array) error {
+	args, err := s.args(input)
+	if err != nil {
+		return s.fail(err)
+	}
+
+	s.columns, s.rows, s.cursor, s.rowCount = nil, nil, 0, 0
+	ctx := context.Background()
+
+	if !returnsRows(s.rewritten) {
+		result, err := s.conn.queryer().ExecContext(ctx, s.rewritten, args...)
+		if err != nil {
+			return s.fail(err)
+		}
+		s.conn.recordInsertID(result)
+		if affected, err := result.RowsAffected(); err == nil {
+			s.rowCount = affected
+		}
+		s.err, s.conn.err = noError, noError
+		return nil
+	}
+
+	rows, err := s.conn.queryer().QueryContext(ctx, s.rewritten, args...)
+	if err != nil {
+		return s.fail(err)
+	}
+	defer rows.Close()
+
+	if s.columns, err = rows.Columns(); err != nil {
+		return s.fail(err)
+	}
+	for rows.Next() {
+		row := make([]any, len(s.columns))
+		scan := make([]any, len(row))
+		for i := range row {
+			scan[i] = &row[i]
+		}
+		if err := rows.Scan(scan...); err != nil {
+			return s.fail(err)
+		}
+		s.rows = append(s.rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return s.fail(err)
+	}
+
+	s.rowCount = int64(len(s.rows))
+	s.err, s.conn.err = noError, noError
+	return nil
+}
+
+// ColumnCount returns the number of columns in the result set
+func (s *Statement) ColumnCount() int {
+	return len(s.columns)
+}
+
+// RowCount returns the number of rows affected by the statement, or
+// returned by a query
+func (s *Statement) RowCount() int64 {
+	return s.rowCount
+}
+
+// CloseCursor frees the result set, so the statement can be executed again
+func (s *Statement) CloseCursor() {
+	s.rows, s.cursor = nil, 0
+}
+
+// SetFetchMode sets the default fetch mode of the statement; arg is the
+// column index for FETCH_COLUMN
+func (s *Statement) SetFetchMode(mode int, arg *types.Value) {
+	s.fetchMode, s.fetchArg = mode, arg
+}
+
+// mode resolves FETCH_DEFAULT to the statement's or connection's mode
+func (s *Statement) mode(mode int) int {
+	if mode == FETCH_DEFAULT {
+		mode = s.fetchMode
+	}
+	if mode == FETCH_DEFAULT {
+		mode = s.conn.defaultFetchMode
+	}
+	return mode
+}
+
+// columnName applies ATTR_CASE to a column name
+func (s *Statement) columnName(i int) string {
+	switch s.conn.columnCase {
+	case CASE_UPPER:
+		return strings.ToUpper(s.columns[i])
+	case CASE_LOWER:
+		return strings.ToLower(s.columns[i])
+	}
+	return s.columns[i]
+}
+
+// columnValue converts a value scanned from the driver
+func (s *Statement) columnValue(v any) *types.Value {
+	var value *types.Value
+	switch v := v.(type) {
+	case nil:
+		return types.NewNull()
+	case int64:
+		value = types.NewInt(v)
+	case float64:
+		value = types.NewFloat(v)
+	case bool:
+		value = types.NewBool(v)
+	case []byte:
+		return types.NewString(string(v))
+	case string:
+		return types.NewString(v)
+	case time.Time:
+		return types.NewString(v.Format("2006-01-02 15:04:05"))
+	default:
+		return types.NewString(fmt.Sprint(v))
+	}
+	if s.conn.stringify {
+		return types.NewString(value.ToString())
+	}
+	return value
+}
+
+// Fetch returns the next row in the given fetch mode, or false when there
+// are no more rows
+func (s *Statement) Fetch(mode int) (*types.Value, error) {
+	if s.cursor >= len(s.rows) {
+		return types.NewBool(false), nil
+	}
+	row := s.rows[s.cursor]
+	s.cursor++
+
+	switch mode = s.mode(mode); mode {
+	case FETCH_ASSOC, FETCH_NUM, FETCH_BOTH:
+		arr := types.NewArrayWithCapacity(len(row))
+		for i, v := range row {
+			value := s.columnValue(v)
+			if mode != FETCH_NUM {
+				arr.Set(types.NewString(s.columnName(i)), value)
+			}
+			if mode != FETCH_ASSOC {
+				arr.Set(types.NewInt(int64(i)), value)
+			}
+		}
+		return types.NewArray(arr), nil
+
+	case FETCH_OBJ:
+		obj := types.NewObjectInstance("stdClass")
+		for i, v := range row {
+			obj.AddProperty(s.columnName(i), &types.Property{Value: s.columnValue(v), Visibility: types.VisibilityPublic})
+		}
+		return types.NewObject(obj), nil
+
+	case FETCH_COLUMN:
+		column := 0
+		if s.fetchArg != nil {
+			column = int(s.fetchArg.ToInt())
+		}
+		return s.column(row, column)
+
+	default:
+		s.cursor--
+		return nil, s.fail(&Error{SQLState: "HY000", Message: fmt.Sprintf("General error: fetch mode %d is not supported", mode)})
+	}
+}
+
+// column returns one column of a row
+func (s *Statement) column(row []any, column int) (*types.Value, error) {
+	if column < 0 || column >= len(row) {
+		return nil, s.fail(&Error{SQLState: "HY000", Message: "General error: Invalid column index"})
+	}
+	return s.columnValue(row[column]), nil
+}
+
+// FetchColumn returns a column of the next row, or false when there are no
+// more rows
+func (s *Statement) FetchColumn(column int) (*types.Value, error) {
+	if s.cursor >= len(s.rows) {
+		return types.NewBool(false), nil
+	}
+	value, err := s.column(s.rows[s.cursor], column)
+	if err != nil {
+		return nil, err
+	}
+	s.cursor++
+	return value, nil
+}
+
+// FetchAll returns the remaining rows. arg is the column index for
+// FETCH_COLUMN.
+func (s *Statement) FetchAll(mode int, arg *types.Value) (*types.Value, error) {
+	result := types.NewEmptyArray()
+	mode = s.mode(mode)
+
+	switch mode {
+	case FETCH_COLUMN:
+		column := 0
+		if arg != nil {
+			column = int(arg.ToInt())
+		} else if s.fetchArg != nil {
+			column = int(s.fetchArg.ToInt())
+		}
+		for ; s.cursor < len(s.rows); s.cursor++ {
+			value, err := s.column(s.rows[s.cursor], column)
+			if err != nil {
+				return nil, err
+			}
+			result.Append(value)
+		}
+
+	case FETCH_KEY_PAIR:
+		if len(s.columns) != 2 {
+			return nil, s.fail(&Error{SQLState: "HY000", Message: "General error: FETCH_KEY_PAIR fetch mode requires the result set to contain exactly 2 columns"})
+		}
+		for ; s.cursor < len(s.rows); s.cursor++ {
+			row := s.rows[s.cursor]
+			result.Set(s.columnValue(row[0]), s.columnValue(row[1]))
+		}
+
+	default:
+		for s.cursor < len(s.rows) {
+			row, err := s.Fetch(mode)
+			if err != nil {
+				return nil, err
+			}
+			result.Append(row)
+		}
+	}
+	return types.NewArray(result), nil
+}
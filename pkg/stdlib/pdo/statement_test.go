@@ -0,0 +1,160 @@
+package pdo
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Query Rewriting Tests
+// ============================================================================
+
+func TestRewriteQuery(t *testing.T) {
+	dollar := func(n int) string { return fmt.Sprintf("$%d", n) }
+
+	tests := []struct {
+		query       string
+		placeholder func(int) string
+		expected    string
+		params      string
+	}{
+		{"SELECT * FROM t WHERE a = ? AND b = ?", nil, "SELECT * FROM t WHERE a = ? AND b = ?", "?,?"},
+		{"SELECT * FROM t WHERE a = :a AND b = :b_2", nil, "SELECT * FROM t WHERE a = ? AND b = ?", ":a,:b_2"},
+		{"SELECT * FROM t WHERE a = :a OR b = :a", dollar, "SELECT * FROM t WHERE a = $1 OR b = $2", ":a,:a"},
+		{"SELECT ':no', 'it''s ?', \"?\" FROM t WHERE a = :a", nil, "SELECT ':no', 'it''s ?', \"?\" FROM t WHERE a = ?", ":a"},
+		{"SELECT a::text FROM t -- :no ?\nWHERE b = ?", dollar, "SELECT a::text FROM t -- :no ?\nWHERE b = $1", "?"},
+		{"SELECT /* :no */ 1", nil, "SELECT /* :no */ 1", ""},
+	}
+	for _, tt := range tests {
+		rewritten, params, err := rewriteQuery(tt.query, tt.placeholder)
+		if err != nil {
+			t.Errorf("rewriteQuery(%q): %v", tt.query, err)
+			continue
+		}
+		if rewritten != tt.expected || strings.Join(params, ",") != tt.params {
+			t.Errorf("rewriteQuery(%q) = %q %v, want %q %s", tt.query, rewritten, params, tt.expected, tt.params)
+		}
+	}
+
+	if _, _, err := rewriteQuery("SELECT ? + :a", nil); err == nil {
+		t.Error("Expected mixed named and positional parameters to fail")
+	}
+}
+
+// ============================================================================
+// Statement Tests
+// ============================================================================
+
+// usersResult is the result of the users query of the fake database
+var usersResult = fakeResult{
+	columns: []string{"id", "name"},
+	rows: [][]driver.Value{
+		{int64(1), []byte("ann")},
+		{int64(2), nil},
+	},
+}
+
+func TestStatementBindingAndFetchModes(t *testing.T) {
+	db, conn := openFake(t)
+	db.results["SELECT id, name FROM users WHERE id > ? AND name <> ?"] = usersResult
+
+	stmt, err := conn.Prepare("SELECT id, name FROM users WHERE id > :min AND name <> :name")
+	if err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+
+	// bindParam binds a reference, read at execute time
+	min := types.NewReference(types.NewInt(0))
+	if err := stmt.Bind(types.NewString(":min"), min, PARAM_INT); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	if err := stmt.Bind(types.NewString("name"), types.NewString("bob"), PARAM_STR); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	if err := stmt.Bind(types.NewString(":missing"), types.NewInt(1), PARAM_INT); err == nil {
+		t.Error("Expected binding an unknown parameter to fail")
+	}
+	min.Assign(types.NewString("5"))
+
+	if err := stmt.Execute(nil); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got := db.log[len(db.log)-1]; !strings.HasSuffix(got, "[5 bob]") {
+		t.Errorf("Expected the reference to be read at execute time, got %s", got)
+	}
+	if stmt.ColumnCount() != 2 || stmt.RowCount() != 2 {
+		t.Errorf("Expected 2 columns and 2 rows, got %d and %d", stmt.ColumnCount(), stmt.RowCount())
+	}
+
+	row, _ := stmt.Fetch(FETCH_ASSOC)
+	if name, _ := row.ToArray().Get(types.NewString("name")); name.ToString() != "ann" || row.ToArray().Len() != 2 {
+		t.Errorf("Expected an associative row, got %s", row.ToArray())
+	}
+	row, _ = stmt.Fetch(FETCH_OBJ)
+	if obj := row.ToObject(); obj.ClassName != "stdClass" || obj.Properties["id"].Value.ToInt() != 2 || !obj.Properties["name"].Value.IsNull() {
+		t.Errorf("Expected a stdClass row, got %v", row)
+	}
+	if row, _ = stmt.Fetch(FETCH_DEFAULT); row.ToBool() {
+		t.Errorf("Expected false after the last row, got %v", row)
+	}
+}
+
+func TestStatementExecuteWithInput(t *testing.T) {
+	db, conn := openFake(t)
+	db.results["SELECT id, name FROM users WHERE id IN (?, ?)"] = usersResult
+
+	stmt, _ := conn.Prepare("SELECT id, name FROM users WHERE id IN (?, ?)")
+	input := types.NewArrayFromSlice([]*types.Value{types.NewInt(1), types.NewInt(2)})
+	if err := stmt.Execute(input); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	all, _ := stmt.FetchAll(FETCH_BOTH, nil)
+	first, _ := all.ToArray().Get(types.NewInt(0))
+	if first.ToArray().Len() != 4 {
+		t.Errorf("Expected FETCH_BOTH to index by name and number, got %s", first.ToArray())
+	}
+
+	stmt.Execute(input)
+	names, _ := stmt.FetchAll(FETCH_COLUMN, types.NewInt(1))
+	if got := names.ToArray().String(); !strings.Contains(got, "ann") || names.ToArray().Len() != 2 {
+		t.Errorf("Expected the name column, got %s", got)
+	}
+
+	stmt.Execute(input)
+	pairs, _ := stmt.FetchAll(FETCH_KEY_PAIR, nil)
+	if name, _ := pairs.ToArray().Get(types.NewInt(1)); name.ToString() != "ann" {
+		t.Errorf("Expected id => name pairs, got %s", pairs.ToArray())
+	}
+
+	err := stmt.Execute(types.NewArrayFromSlice([]*types.Value{types.NewInt(1)}))
+	var pdoErr *Error
+	if !errors.As(err, &pdoErr) || pdoErr.SQLState != "HY093" {
+		t.Errorf("Expected HY093 for a missing parameter, got %v", err)
+	}
+}
+
+func TestStatementExecAndInsertID(t *testing.T) {
+	_, conn := openFake(t)
+
+	stmt, _ := conn.Prepare("INSERT INTO users (name) VALUES (:name)")
+	for _, name := range []string{"ann", "bob"} {
+		input := types.NewEmptyArray()
+		input.Set(types.NewString("name"), types.NewString(name))
+		if err := stmt.Execute(input); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	}
+	if stmt.RowCount() != 1 || conn.LastInsertID() != "2" {
+		t.Errorf("Expected 1 affected row and insert ID 2, got %d and %s", stmt.RowCount(), conn.LastInsertID())
+	}
+
+	if _, err := conn.Query("SELECT * FROM missing"); err == nil || conn.LastError().SQLState != "HY000" {
+		t.Errorf("Expected a query on a missing table to fail, got %v", err)
+	}
+}
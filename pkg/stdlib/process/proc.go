@@ -0,0 +1,426 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// popen()
+// ============================================================================
+
+// pipeHandle is the stream handle of popen(); closing it waits for the
+// command to exit
+type pipeHandle struct {
+	*os.File
+	cmd    *exec.Cmd
+	status int
+}
+
+func (h *pipeHandle) Close() error {
+	err := h.File.Close()
+	h.cmd.Wait()
+	h.status = exitStatus(h.cmd)
+	return err
+}
+
+// Popen runs a command through the shell and returns a stream reading
+// its output (mode "r") or writing its input (mode "w")
+// popen(string $command, string $mode): resource|false
+func Popen(command, mode string) (*types.Value, error) {
+	if err := checkCommand("popen", command); err != nil {
+		return nil, err
+	}
+	switch mode {
+	case "r", "rb", "w", "wb":
+	default:
+		return nil, &Error{"ValueError", `popen(): Argument #2 ($mode) must be one of "r", "rb", "w", or "wb"`}
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return types.NewBool(false), fmt.Errorf("popen(%s,%s): %s", command, mode, err)
+	}
+	cmd := shellCommand(command)
+	cmd.Stdout = os.Stdout
+	parent, child := reader, writer
+	if mode[0] == 'w' {
+		cmd.Stdin = reader
+		parent, child = writer, reader
+	} else {
+		cmd.Stdout = writer
+	}
+	err = cmd.Start()
+	child.Close()
+	if err != nil {
+		parent.Close()
+		return types.NewBool(false), fmt.Errorf("popen(%s,%s): %s", command, mode, err)
+	}
+	streamMode, _ := types.ParseStreamMode(mode)
+	stream := types.NewPipeStream(&pipeHandle{File: parent, cmd: cmd}, command, streamMode)
+	return types.NewResource(types.NewStreamResource(stream)), nil
+}
+
+// Pclose closes a stream opened by Popen and returns the exit status of
+// its command
+// pclose(resource $handle): int
+func Pclose(handle *types.Value) *types.Value {
+	if handle.Type() != types.TypeResource {
+		return types.NewInt(-1)
+	}
+	res := handle.ToResource()
+	stream, ok := res.Data().(*types.Stream)
+	if !ok {
+		return types.NewInt(-1)
+	}
+	pipe, ok := stream.Handle().(*pipeHandle)
+	if !ok {
+		return types.NewInt(-1)
+	}
+	res.Close()
+	return types.NewInt(int64(pipe.status))
+}
+
+// ============================================================================
+// proc_open()
+// ============================================================================
+
+// ProcessResourceType is the resource type label of proc_open() processes
+const ProcessResourceType = "process"
+
+// Process is a command started by proc_open()
+type Process struct {
+	cmd     *exec.Cmd
+	command string
+	done    chan struct{}
+}
+
+// reap waits for the process to exit in the background, so that its
+// status can be polled
+func (p *Process) reap() {
+	p.cmd.Wait()
+	close(p.done)
+}
+
+// exited reports whether the process has exited
+func (p *Process) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// processOf returns the process behind a resource value
+func processOf(value *types.Value) (*Process, bool) {
+	if value.Type() != types.TypeResource || value.ToResource().Type() != ProcessResourceType {
+		return nil, false
+	}
+	p, ok := value.ToResource().Data().(*Process)
+	return p, ok
+}
+
+// descriptor is the file a child process gets for a descriptor number,
+// with the parent's end of a pipe
+type descriptor struct {
+	child      *os.File
+	parent     *os.File
+	parentMode string
+	// owned files are opened for the child and closed once it starts
+	owned bool
+}
+
+// specError is the ValueError of an invalid descriptor spec
+func specError(format string, args ...any) error {
+	return &Error{"ValueError", "proc_open(): " + fmt.Sprintf(format, args...)}
+}
+
+// openDescriptor opens the file a descriptor spec entry describes: a
+// stream resource or an array of "pipe" and its mode, "file" with a path
+// and mode, "redirect" with another descriptor number or "null"
+func openDescriptor(spec *types.Value, opened map[int64]*descriptor) (*descriptor, error) {
+	if spec.Type() == types.TypeResource {
+		stream, ok := spec.ToResource().Data().(*types.Stream)
+		if !ok {
+			return nil, specError("Argument #2 ($descriptor_spec) must only contain arrays and streams")
+		}
+		switch h := stream.Handle().(type) {
+		case *os.File:
+			return &descriptor{child: h}, nil
+		case *pipeHandle:
+			return &descriptor{child: h.File}, nil
+		}
+		return nil, fmt.Errorf("proc_open(): Cannot represent a stream of type %s as a File Descriptor", streamType(stream))
+	}
+	if spec.Type() != types.TypeArray {
+		return nil, specError("Argument #2 ($descriptor_spec) must only contain arrays and streams")
+	}
+	entry := spec.ToArray()
+	arg := func(i int64) (string, bool) {
+		v, ok := entry.Get(types.NewInt(i))
+		if !ok {
+			return "", false
+		}
+		return v.ToString(), true
+	}
+	kind, ok := arg(0)
+	if !ok {
+		return nil, specError("Missing handle qualifier in array")
+	}
+	switch kind {
+	case "pipe":
+		mode, ok := arg(1)
+		if !ok {
+			return nil, specError(`Missing mode parameter for "pipe"`)
+		}
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return nil, fmt.Errorf("proc_open(): Unable to create pipe %s", err)
+		}
+		// The mode is the child's: it reads an "r" pipe the parent writes
+		if strings.HasPrefix(mode, "r") {
+			return &descriptor{child: reader, parent: writer, parentMode: "w", owned: true}, nil
+		}
+		return &descriptor{child: writer, parent: reader, parentMode: "r", owned: true}, nil
+	case "file":
+		path, ok := arg(1)
+		if !ok {
+			return nil, specError(`Missing file name parameter for "file"`)
+		}
+		mode, ok := arg(2)
+		if !ok {
+			return nil, specError(`Missing mode parameter for "file"`)
+		}
+		m, err := types.ParseStreamMode(mode)
+		if err != nil {
+			return nil, fmt.Errorf("proc_open(%s): Failed to open stream: %s", path, err)
+		}
+		file, err := os.OpenFile(path, m.OpenFlags(), 0666)
+		if err != nil {
+			return nil, fmt.Errorf("proc_open(%s): Failed to open stream: %s", path, describe(err))
+		}
+		return &descriptor{child: file, owned: true}, nil
+	case "redirect":
+		target, ok := entry.Get(types.NewInt(1))
+		if !ok {
+			return nil, specError("Missing redirection target")
+		}
+		d, ok := opened[target.ToInt()]
+		if !ok {
+			return nil, specError("Redirection target %d not found", target.ToInt())
+		}
+		return &descriptor{child: d.child}, nil
+	case "null":
+		file, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+		if err != nil {
+			return nil, fmt.Errorf("proc_open(): Failed to open %s: %s", os.DevNull, describe(err))
+		}
+		return &descriptor{child: file, owned: true}, nil
+	}
+	return nil, specError("%s is not a valid descriptor spec/mode", kind)
+}
+
+// streamType names the kind of a stream in errors
+func streamType(stream *types.Stream) string {
+	if stream.IsSocket() {
+		return "tcp_socket"
+	}
+	return "STDIO"
+}
+
+// describe returns the message of an OS error without the operation and
+// path, as strerror() does
+func describe(err error) string {
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		message := errno.Error()
+		return strings.ToUpper(message[:1]) + message[1:]
+	}
+	return err.Error()
+}
+
+// ProcOptions are the working directory and environment of proc_open();
+// an empty Dir and a nil Env are inherited
+type ProcOptions struct {
+	Dir string
+	Env *types.Array
+}
+
+// ProcOpen starts a command, a shell command string or an array of the
+// program and its arguments, with the descriptors of spec. It returns the
+// process resource and the streams of the parent's ends of the pipes,
+// keyed by descriptor number.
+// proc_open(array|string $command, array $descriptor_spec, array &$pipes, ?string $cwd = null, ?array $env_vars = null, ?array $options = null): resource|false
+func ProcOpen(command *types.Value, spec *types.Array, opts ProcOptions) (*types.Value, *types.Array, error) {
+	cmd, name, err := procCommand(command)
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd.Dir = opts.Dir
+	if opts.Env != nil {
+		env := []string{}
+		opts.Env.Each(func(key, value *types.Value) bool {
+			env = append(env, key.ToString()+"="+value.Deref().ToString())
+			return true
+		})
+		cmd.Env = env
+	}
+
+	opened := make(map[int64]*descriptor)
+	closeAll := func(parents bool) {
+		for _, d := range opened {
+			if d.owned {
+				d.child.Close()
+			}
+			if parents && d.parent != nil {
+				d.parent.Close()
+			}
+		}
+	}
+	var order []*types.Value
+	spec.Each(func(key, value *types.Value) bool {
+		if key.Type() != types.TypeInt {
+			err = specError("Argument #2 ($descriptor_spec) must be an integer indexed array")
+			return false
+		}
+		var d *descriptor
+		if d, err = openDescriptor(value.Deref(), opened); err != nil {
+			return false
+		}
+		opened[key.ToInt()] = d
+		order = append(order, key)
+		return true
+	})
+	if err != nil {
+		closeAll(true)
+		return nil, nil, err
+	}
+
+	// Descriptors 0 to 2 not in the spec are inherited
+	child := func(fd int64, inherited *os.File) *os.File {
+		if d, ok := opened[fd]; ok {
+			return d.child
+		}
+		return inherited
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = child(0, os.Stdin), child(1, os.Stdout), child(2, os.Stderr)
+	for fd := range opened {
+		if fd > 2 {
+			for int64(len(cmd.ExtraFiles)) < fd-2 {
+				cmd.ExtraFiles = append(cmd.ExtraFiles, nil)
+			}
+			cmd.ExtraFiles[fd-3] = opened[fd].child
+		}
+	}
+
+	err = cmd.Start()
+	closeAll(err != nil)
+	if errors.Is(err, exec.ErrNotFound) {
+		err = syscall.ENOENT
+	}
+	if err != nil {
+		return types.NewBool(false), nil, fmt.Errorf("proc_open(): Exec failed: %s", describe(err))
+	}
+
+	pipes := types.NewEmptyArray()
+	for _, key := range order {
+		if d := opened[key.ToInt()]; d.parent != nil {
+			streamMode, _ := types.ParseStreamMode(d.parentMode)
+			stream := types.NewPipeStream(d.parent, "", streamMode)
+			pipes.Set(key, types.NewResource(types.NewStreamResource(stream)))
+		}
+	}
+
+	p := &Process{cmd: cmd, command: name, done: make(chan struct{})}
+	go p.reap()
+	return types.NewResource(types.NewResourceHandle(ProcessResourceType, p)), pipes, nil
+}
+
+// procCommand builds the command of proc_open(): strings run through the
+// shell and arrays run the program directly
+func procCommand(command *types.Value) (*exec.Cmd, string, error) {
+	if command.Type() != types.TypeArray {
+		name := command.ToString()
+		if strings.IndexByte(name, 0) >= 0 {
+			return nil, "", &Error{"ValueError", "proc_open(): Argument #1 ($command) must not contain any null bytes"}
+		}
+		return exec.Command(Shell[0], append(Shell[1:], name)...), name, nil
+	}
+	var argv []string
+	var err error
+	command.ToArray().Each(func(_, value *types.Value) bool {
+		arg := value.Deref().ToString()
+		if strings.IndexByte(arg, 0) >= 0 {
+			err = &Error{"ValueError", fmt.Sprintf("Command array element %d contains a null byte", len(argv)+1)}
+			return false
+		}
+		argv = append(argv, arg)
+		return true
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(argv) == 0 {
+		return nil, "", &Error{"ValueError", "proc_open(): Argument #1 ($command) must have at least one element"}
+	}
+	return exec.Command(argv[0], argv[1:]...), argv[0], nil
+}
+
+// ProcGetStatus returns the command, pid and state of a process
+// proc_get_status(resource $process): array
+func ProcGetStatus(process *types.Value) *types.Value {
+	p, ok := processOf(process)
+	if !ok {
+		return types.NewBool(false)
+	}
+	running := !p.exited()
+	exitCode, signaled, termsig := int64(-1), false, int64(0)
+	if !running {
+		exitCode = int64(p.cmd.ProcessState.ExitCode())
+		if status, ok := p.cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			signaled, termsig = true, int64(status.Signal())
+		}
+	}
+	status := types.NewEmptyArray()
+	set := func(key string, value *types.Value) { status.Set(types.NewString(key), value) }
+	set("command", types.NewString(p.command))
+	set("pid", types.NewInt(int64(p.cmd.Process.Pid)))
+	set("cached", types.NewBool(!running))
+	set("running", types.NewBool(running))
+	set("signaled", types.NewBool(signaled))
+	set("stopped", types.NewBool(false))
+	set("exitcode", types.NewInt(exitCode))
+	set("termsig", types.NewInt(termsig))
+	set("stopsig", types.NewInt(0))
+	return types.NewArray(status)
+}
+
+// ProcClose waits for a process to exit, frees it and returns its exit
+// status
+// proc_close(resource $process): int
+func ProcClose(process *types.Value) *types.Value {
+	p, ok := processOf(process)
+	if !ok {
+		return types.NewInt(-1)
+	}
+	<-p.done
+	process.ToResource().Close()
+	return types.NewInt(int64(exitStatus(p.cmd)))
+}
+
+// ProcTerminate sends a signal, SIGTERM by default, to a process
+// proc_terminate(resource $process, int $signal = 15): bool
+func ProcTerminate(process *types.Value, signal int64) *types.Value {
+	p, ok := processOf(process)
+	if !ok || p.exited() {
+		return types.NewBool(false)
+	}
+	return types.NewBool(p.cmd.Process.Signal(syscall.Signal(signal)) == nil)
+}
@@ -0,0 +1,220 @@
+// Package process implements PHP's program execution functions on top of
+// os/exec: exec(), shell_exec(), system(), passthru(), popen(),
+// proc_open() and the shell quoting helpers.
+package process
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// Error is an error thrown by a process function, such as the ValueError
+// of an empty command
+type Error struct {
+	Class   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Shell runs the command strings, as PHP does with /bin/sh -c
+var Shell = []string{"/bin/sh", "-c"}
+
+// shellCommand returns the command that runs a command string
+func shellCommand(command string) *exec.Cmd {
+	cmd := exec.Command(Shell[0], append(Shell[1:], command)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// checkCommand validates the command argument of function
+func checkCommand(function, command string) error {
+	if command == "" {
+		return &Error{"ValueError", fmt.Sprintf("%s(): Argument #1 ($command) cannot be empty", function)}
+	}
+	if strings.IndexByte(command, 0) >= 0 {
+		return &Error{"ValueError", fmt.Sprintf("%s(): Argument #1 ($command) must not contain any null bytes", function)}
+	}
+	return nil
+}
+
+// exitStatus returns the exit code of a finished command; a command
+// killed by a signal has -1
+func exitStatus(cmd *exec.Cmd) int {
+	if cmd.ProcessState == nil {
+		return -1
+	}
+	return cmd.ProcessState.ExitCode()
+}
+
+// run starts a command string with its output going to stdout and waits
+// for it. The error is only set when the shell cannot be started.
+func run(function, command string, stdout io.Writer) (int, error) {
+	if err := checkCommand(function, command); err != nil {
+		return -1, err
+	}
+	cmd := shellCommand(command)
+	cmd.Stdout = stdout
+	if err := cmd.Start(); err != nil {
+		return -1, fmt.Errorf("%s(): Unable to fork [%s]", function, command)
+	}
+	var exitErr *exec.ExitError
+	if err := cmd.Wait(); err != nil && !errors.As(err, &exitErr) {
+		return -1, fmt.Errorf("%s(): %s", function, err)
+	}
+	return exitStatus(cmd), nil
+}
+
+// ============================================================================
+// Execution
+// ============================================================================
+
+// Exec runs a command and returns the lines of its output, without their
+// trailing whitespace, and its exit status
+// exec(string $command, array &$output = null, int &$result_code = null): string|false
+func Exec(command string) ([]string, int, error) {
+	var out bytes.Buffer
+	status, err := run("exec", command, &out)
+	if err != nil {
+		return nil, status, err
+	}
+	var lines []string
+	scanner := bufio.NewScanner(&out)
+	scanner.Buffer(nil, out.Len()+1)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), " \t\n\r\v\f"))
+	}
+	return lines, status, nil
+}
+
+// ShellExec runs a command through the shell and returns its whole
+// output, or null when there is none
+// shell_exec(string $command): string|false|null
+func ShellExec(command string) (*types.Value, error) {
+	var out bytes.Buffer
+	if _, err := run("shell_exec", command, &out); err != nil {
+		if _, thrown := err.(*Error); thrown {
+			return nil, err
+		}
+		return types.NewBool(false), err
+	}
+	if out.Len() == 0 {
+		return types.NewNull(), nil
+	}
+	return types.NewString(out.String()), nil
+}
+
+// lineWriter passes the output of system() on line by line and keeps the
+// last line
+type lineWriter struct {
+	output  func(data string)
+	pending []byte
+	last    string
+}
+
+func (w *lineWriter) Write(data []byte) (int, error) {
+	w.pending = append(w.pending, data...)
+	if i := bytes.LastIndexByte(w.pending, '\n'); i >= 0 {
+		w.flush(i + 1)
+	}
+	return len(data), nil
+}
+
+// flush outputs the first n pending bytes
+func (w *lineWriter) flush(n int) {
+	if n == 0 {
+		return
+	}
+	chunk := string(w.pending[:n])
+	w.pending = w.pending[n:]
+	w.output(chunk)
+	lines := strings.Split(strings.TrimSuffix(chunk, "\n"), "\n")
+	w.last = lines[len(lines)-1]
+}
+
+// System runs a command, passing its output on as each line completes, and
+// returns the last line of the output without trailing whitespace with
+// the exit status
+// system(string $command, int &$result_code = null): string|false
+func System(command string, output func(data string)) (string, int, error) {
+	w := &lineWriter{output: output}
+	status, err := run("system", command, w)
+	if err != nil {
+		return "", status, err
+	}
+	w.flush(len(w.pending))
+	return strings.TrimRight(w.last, " \t\n\r\v\f"), status, nil
+}
+
+// outputWriter passes raw output on
+type outputWriter func(data string)
+
+func (w outputWriter) Write(data []byte) (int, error) {
+	w(string(data))
+	return len(data), nil
+}
+
+// Passthru runs a command, passing its raw output on, and returns the exit
+// status
+// passthru(string $command, int &$result_code = null): ?false
+func Passthru(command string, output func(data string)) (int, error) {
+	return run("passthru", command, outputWriter(output))
+}
+
+// ============================================================================
+// Quoting
+// ============================================================================
+
+// EscapeShellArg quotes a string to be passed as a single shell argument
+// escapeshellarg(string $arg): string
+func EscapeShellArg(arg string) (string, error) {
+	if strings.IndexByte(arg, 0) >= 0 {
+		return "", &Error{"ValueError", "escapeshellarg(): Argument #1 ($arg) must not contain any null bytes"}
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'", nil
+}
+
+// EscapeShellCmd escapes the shell metacharacters of a command; quotes
+// are only escaped when they are not paired
+// escapeshellcmd(string $command): string
+func EscapeShellCmd(command string) (string, error) {
+	if strings.IndexByte(command, 0) >= 0 {
+		return "", &Error{"ValueError", "escapeshellcmd(): Argument #1 ($command) must not contain any null bytes"}
+	}
+	var b strings.Builder
+	// open is the quote kept unescaped because a later one pairs it
+	var open byte
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch c {
+		case '"', '\'':
+			if open == 0 {
+				if strings.IndexByte(command[i+1:], c) >= 0 {
+					open = c
+				} else {
+					b.WriteByte('\\')
+				}
+			} else if open == c {
+				open = 0
+			} else {
+				b.WriteByte('\\')
+			}
+		case '#', '&', ';', '`', '|', '*', '?', '~', '<', '>', '^', '(', ')',
+			'[', ']', '{', '}', '$', '\\', '\n', '\xff':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,186 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestExec(t *testing.T) {
+	lines, status, err := Exec("printf 'one  \\n\\ntwo\\n'; exit 3")
+	if err != nil || status != 3 {
+		t.Fatalf("Exec = %v, %d, %v", lines, status, err)
+	}
+	if strings.Join(lines, "|") != "one||two" {
+		t.Errorf("lines = %q", lines)
+	}
+
+	if _, _, err := Exec(""); err == nil || err.Error() != "exec(): Argument #1 ($command) cannot be empty" {
+		t.Errorf("empty command: %v", err)
+	}
+	if result, _ := ShellExec("echo hi"); result.ToString() != "hi\n" {
+		t.Errorf("ShellExec = %v", result)
+	}
+	if result, _ := ShellExec("true"); !result.IsNull() {
+		t.Errorf("ShellExec without output = %v", result)
+	}
+}
+
+func TestSystemAndPassthru(t *testing.T) {
+	var out strings.Builder
+	last, status, err := System("printf 'a\\nb  \\n'", func(data string) { out.WriteString(data) })
+	if err != nil || last != "b" || status != 0 || out.String() != "a\nb  \n" {
+		t.Errorf("System = %q, %d, %v, output %q", last, status, err, out.String())
+	}
+
+	out.Reset()
+	status, _ = Passthru("printf 'raw'; exit 2", func(data string) { out.WriteString(data) })
+	if status != 2 || out.String() != "raw" {
+		t.Errorf("Passthru = %d, output %q", status, out.String())
+	}
+}
+
+func TestEscapeShell(t *testing.T) {
+	for arg, want := range map[string]string{
+		"plain":    "'plain'",
+		"it's":     `'it'\''s'`,
+		"$HOME; x": "'$HOME; x'",
+	} {
+		if got, _ := EscapeShellArg(arg); got != want {
+			t.Errorf("EscapeShellArg(%q) = %q, want %q", arg, got, want)
+		}
+	}
+	for command, want := range map[string]string{
+		"ls -l; rm *":      `ls -l\; rm \*`,
+		`echo "a b" 'c'`:   `echo "a b" 'c'`,
+		`echo "unpaired`:   `echo \"unpaired`,
+		`say "it's" $x`:    `say "it\'s" \$x`,
+		"a`id`|b&c>d<e(f)": "a\\`id\\`\\|b\\&c\\>d\\<e\\(f\\)",
+	} {
+		if got, _ := EscapeShellCmd(command); got != want {
+			t.Errorf("EscapeShellCmd(%q) = %q, want %q", command, got, want)
+		}
+	}
+	if _, err := EscapeShellArg("a\x00b"); err == nil {
+		t.Error("null bytes are rejected")
+	}
+}
+
+func TestPopen(t *testing.T) {
+	stream, err := Popen("echo out; exit 4", "r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := stream.ToResource().Data().(*types.Stream)
+	if data, _ := s.Read(100); string(data) != "out\n" {
+		t.Errorf("Read = %q", data)
+	}
+	if status := Pclose(stream); status.ToInt() != 4 {
+		t.Errorf("Pclose = %v", status)
+	}
+
+	path := filepath.Join(t.TempDir(), "out.txt")
+	stream, _ = Popen("cat > "+path, "w")
+	stream.ToResource().Data().(*types.Stream).Write([]byte("written"))
+	if status := Pclose(stream); status.ToInt() != 0 {
+		t.Errorf("Pclose = %v", status)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "written" {
+		t.Errorf("file = %q", data)
+	}
+
+	if _, err := Popen("true", "x"); err == nil || !strings.Contains(err.Error(), "must be one of") {
+		t.Errorf("invalid mode: %v", err)
+	}
+}
+
+// pipeSpec returns a descriptor spec entry
+func pipeSpec(values ...string) *types.Value {
+	entry := types.NewEmptyArray()
+	for _, v := range values {
+		entry.Append(types.NewString(v))
+	}
+	return types.NewArray(entry)
+}
+
+func TestProcOpen(t *testing.T) {
+	spec := types.NewEmptyArray()
+	spec.Set(types.NewInt(0), pipeSpec("pipe", "r"))
+	spec.Set(types.NewInt(1), pipeSpec("pipe", "w"))
+	spec.Set(types.NewInt(2), types.NewArray(types.NewArrayFromMap(map[interface{}]*types.Value{0: types.NewString("redirect"), 1: types.NewInt(1)})))
+	env := types.NewArrayFromMap(map[interface{}]*types.Value{"GREETING": types.NewString("hello")})
+
+	proc, pipes, err := ProcOpen(types.NewString(`read name; echo "$GREETING $name $(pwd)"; echo err >&2; exit 5`), spec, ProcOptions{Dir: "/", Env: env})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pipes.Len() != 2 {
+		t.Fatalf("pipes = %v", pipes)
+	}
+	stdin, _ := pipes.Get(types.NewInt(0))
+	stdout, _ := pipes.Get(types.NewInt(1))
+	in := stdin.ToResource().Data().(*types.Stream)
+	in.Write([]byte("world\n"))
+	stdin.ToResource().Close()
+
+	out := stdout.ToResource().Data().(*types.Stream)
+	var lines []string
+	for {
+		line, err := out.ReadLine(0)
+		if err != nil {
+			break
+		}
+		lines = append(lines, string(line))
+	}
+	if strings.Join(lines, "") != "hello world /\nerr\n" {
+		t.Errorf("output = %q", lines)
+	}
+
+	// The status is polled until the process has exited
+	var status *types.Array
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		status = ProcGetStatus(proc).ToArray()
+		if running, _ := status.Get(types.NewString("running")); !running.ToBool() {
+			break
+		}
+	}
+	if code, _ := status.Get(types.NewString("exitcode")); code.ToInt() != 5 {
+		t.Errorf("proc_get_status() = %v", status)
+	}
+	if code := ProcClose(proc); code.ToInt() != 5 {
+		t.Errorf("ProcClose = %v", code)
+	}
+}
+
+func TestProcTerminateAndErrors(t *testing.T) {
+	command := types.NewEmptyArray()
+	command.Append(types.NewString("sleep"))
+	command.Append(types.NewString("10"))
+	proc, _, err := ProcOpen(types.NewArray(command), types.NewEmptyArray(), ProcOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ProcTerminate(proc, 9).ToBool() {
+		t.Fatal("ProcTerminate failed")
+	}
+	if code := ProcClose(proc); code.ToInt() != -1 {
+		t.Errorf("a killed process exits with -1: %v", code)
+	}
+
+	missing := types.NewEmptyArray()
+	missing.Append(types.NewString("/nonexistent/program"))
+	if _, _, err := ProcOpen(types.NewArray(missing), types.NewEmptyArray(), ProcOptions{}); err == nil || err.Error() != "proc_open(): Exec failed: No such file or directory" {
+		t.Errorf("missing program: %v", err)
+	}
+	bad := types.NewArrayFromMap(map[interface{}]*types.Value{0: pipeSpec("socket")})
+	if _, _, err := ProcOpen(types.NewString("true"), bad, ProcOptions{}); err == nil || err.Error() != "proc_open(): socket is not a valid descriptor spec/mode" {
+		t.Errorf("invalid spec: %v", err)
+	}
+	if _, _, err := ProcOpen(types.NewArray(types.NewEmptyArray()), types.NewEmptyArray(), ProcOptions{}); err == nil {
+		t.Error("an empty command array is rejected")
+	}
+}
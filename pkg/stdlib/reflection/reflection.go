@@ -0,0 +1,272 @@
+// Package reflection implements the metadata queries behind the Reflection
+// API: modifier flags, member listing in PHP order and type declarations.
+// The VM exposes them through ReflectionClass, ReflectionMethod and friends.
+package reflection
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Modifiers
+// ============================================================================
+
+// Modifier flags of ReflectionMethod, ReflectionProperty,
+// ReflectionClassConstant and ReflectionClass
+const (
+	IS_PUBLIC            = 1
+	IS_PROTECTED         = 2
+	IS_PRIVATE           = 4
+	IS_STATIC            = 16
+	IS_FINAL             = 32
+	IS_ABSTRACT          = 64
+	IS_READONLY          = 128
+	IS_IMPLICIT_ABSTRACT = 16
+	IS_EXPLICIT_ABSTRACT = 64
+	IS_READONLY_CLASS    = 65536 // ReflectionClass::IS_READONLY
+)
+
+// visibilityModifier returns the modifier flag of a visibility
+func visibilityModifier(visibility types.PropertyVisibility) int64 {
+	switch visibility {
+	case types.VisibilityProtected:
+		return IS_PROTECTED
+	case types.VisibilityPrivate:
+		return IS_PRIVATE
+	}
+	return IS_PUBLIC
+}
+
+// MethodModifiers returns the modifiers of a method
+// ReflectionMethod::getModifiers(): int
+func MethodModifiers(method *types.MethodDef) int64 {
+	modifiers := visibilityModifier(method.Visibility)
+	if method.IsStatic {
+		modifiers |= IS_STATIC
+	}
+	if method.IsFinal {
+		modifiers |= IS_FINAL
+	}
+	if method.IsAbstract {
+		modifiers |= IS_ABSTRACT
+	}
+	return modifiers
+}
+
+// PropertyModifiers returns the modifiers of a property
+// ReflectionProperty::getModifiers(): int
+func PropertyModifiers(prop *types.PropertyDef) int64 {
+	modifiers := visibilityModifier(prop.Visibility)
+	if prop.IsStatic {
+		modifiers |= IS_STATIC
+	}
+	if prop.IsReadOnly {
+		modifiers |= IS_READONLY
+	}
+	return modifiers
+}
+
+// ConstantModifiers returns the modifiers of a class constant
+// ReflectionClassConstant::getModifiers(): int
+func ConstantModifiers(constant *types.ClassConstant) int64 {
+	modifiers := visibilityModifier(constant.Visibility)
+	if constant.IsFinal {
+		modifiers |= IS_FINAL
+	}
+	return modifiers
+}
+
+// ClassModifiers returns the modifiers of a class
+// ReflectionClass::getModifiers(): int
+func ClassModifiers(class *types.ClassEntry) int64 {
+	var modifiers int64
+	if class.IsAbstract && !class.IsInterface && !class.IsTrait {
+		modifiers |= IS_EXPLICIT_ABSTRACT
+	}
+	if class.IsFinal {
+		modifiers |= IS_FINAL
+	}
+	if class.IsReadOnly {
+		modifiers |= IS_READONLY_CLASS
+	}
+	return modifiers
+}
+
+// ModifierNames returns the names of method or property modifiers, in
+// declaration order
+// Reflection::getModifierNames(int $modifiers): array
+func ModifierNames(modifiers int64) []string {
+	var names []string
+	if modifiers&(IS_ABSTRACT|IS_EXPLICIT_ABSTRACT) != 0 {
+		names = append(names, "abstract")
+	}
+	if modifiers&IS_FINAL != 0 {
+		names = append(names, "final")
+	}
+	switch {
+	case modifiers&IS_PUBLIC != 0:
+		names = append(names, "public")
+	case modifiers&IS_PROTECTED != 0:
+		names = append(names, "protected")
+	case modifiers&IS_PRIVATE != 0:
+		names = append(names, "private")
+	}
+	if modifiers&IS_STATIC != 0 {
+		names = append(names, "static")
+	}
+	if modifiers&(IS_READONLY|IS_READONLY_CLASS) != 0 {
+		names = append(names, "readonly")
+	}
+	return names
+}
+
+// ============================================================================
+// Members
+// ============================================================================
+
+// FindMethod looks up a method of a class or its ancestors by its case
+// insensitive name
+func FindMethod(class *types.ClassEntry, name string) (*types.MethodDef, bool) {
+	for current := class; current != nil; current = current.ParentClass {
+		if method, ok := current.Methods[name]; ok {
+			return method, true
+		}
+		for methodName, method := range current.Methods {
+			if strings.EqualFold(methodName, name) {
+				return method, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Methods returns the methods of a class: its own methods first, then the
+// ones it inherits, each group in name order
+func Methods(class *types.ClassEntry) []*types.MethodDef {
+	var methods []*types.MethodDef
+	seen := make(map[string]bool)
+	for current := class; current != nil; current = current.ParentClass {
+		var group []*types.MethodDef
+		for name, method := range current.Methods {
+			if key := strings.ToLower(name); !seen[key] {
+				seen[key] = true
+				group = append(group, method)
+			}
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i].Name < group[j].Name })
+		methods = append(methods, group...)
+	}
+	return methods
+}
+
+// FindProperty looks up a declared property of a class, an inherited
+// one included unless it is private to an ancestor
+func FindProperty(class *types.ClassEntry, name string) (*types.PropertyDef, bool) {
+	prop, ok := class.Properties[name]
+	if !ok || !ownProperty(class, prop) {
+		return nil, false
+	}
+	return prop, true
+}
+
+// ownProperty reports whether a property of a class is one of its own
+// rather than a private property of an ancestor
+func ownProperty(class *types.ClassEntry, prop *types.PropertyDef) bool {
+	return prop.Visibility != types.VisibilityPrivate || prop.DeclaringClass == "" ||
+		strings.EqualFold(prop.DeclaringClass, class.Name)
+}
+
+// Properties returns the declared properties of a class in name order,
+// including the inherited ones but the private properties of ancestors
+func Properties(class *types.ClassEntry) []*types.PropertyDef {
+	props := make([]*types.PropertyDef, 0, len(class.Properties))
+	for _, prop := range class.Properties {
+		if ownProperty(class, prop) {
+			props = append(props, prop)
+		}
+	}
+	sort.Slice(props, func(i, j int) bool { return props[i].Name < props[j].Name })
+	return props
+}
+
+// Constants returns the constants of a class and its ancestors in name
+// order, the class overriding the constants it redeclares
+func Constants(class *types.ClassEntry) []*types.ClassConstant {
+	var constants []*types.ClassConstant
+	seen := make(map[string]bool)
+	for current := class; current != nil; current = current.ParentClass {
+		for name, constant := range current.Constants {
+			if !seen[name] {
+				seen[name] = true
+				constants = append(constants, constant)
+			}
+		}
+	}
+	sort.Slice(constants, func(i, j int) bool { return constants[i].Name < constants[j].Name })
+	return constants
+}
+
+// RequiredParameters returns the number of required parameters: the
+// parameters up to the last one without a default value
+func RequiredParameters(params []*types.ParameterDef) int {
+	required := 0
+	for i, param := range params {
+		if !param.HasDefault && !param.IsVariadic {
+			required = i + 1
+		}
+	}
+	return required
+}
+
+// ============================================================================
+// Types
+// ============================================================================
+
+// NamedType is a type declaration as described by ReflectionNamedType
+type NamedType struct {
+	Name       string // Type name, without the leading ? of nullable types
+	AllowsNull bool   // null is accepted
+	Builtin    bool   // Not a class name
+}
+
+// builtinTypes are the type names that do not refer to classes
+var builtinTypes = map[string]bool{
+	"int": true, "float": true, "string": true, "bool": true, "array": true,
+	"object": true, "callable": true, "iterable": true, "mixed": true,
+	"void": true, "null": true, "never": true, "false": true, "true": true,
+}
+
+// ParseType describes a type declaration; ok is false for an empty one
+func ParseType(decl string) (NamedType, bool) {
+	decl = strings.TrimSpace(decl)
+	if decl == "" {
+		return NamedType{}, false
+	}
+
+	var t NamedType
+	if strings.HasPrefix(decl, "?") {
+		t.AllowsNull = true
+		decl = decl[1:]
+	}
+	t.Name = strings.TrimPrefix(decl, "\\")
+	lower := strings.ToLower(t.Name)
+	if builtinTypes[lower] {
+		t.Name = lower
+		t.Builtin = true
+	}
+	if lower == "mixed" || lower == "null" {
+		t.AllowsNull = true
+	}
+	return t, true
+}
+
+// String returns the type as written in a declaration: ?int, Foo
+func (t NamedType) String() string {
+	if t.AllowsNull && t.Name != "mixed" && t.Name != "null" {
+		return "?" + t.Name
+	}
+	return t.Name
+}
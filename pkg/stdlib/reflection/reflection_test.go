@@ -0,0 +1,100 @@
+package reflection
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestModifiers(t *testing.T) {
+	method := &types.MethodDef{Name: "m", Visibility: types.VisibilityProtected, IsStatic: true, IsFinal: true}
+	if got := MethodModifiers(method); got != IS_PROTECTED|IS_STATIC|IS_FINAL {
+		t.Errorf("MethodModifiers() = %d", got)
+	}
+	prop := &types.PropertyDef{Name: "p", Visibility: types.VisibilityPrivate, IsReadOnly: true}
+	if got := PropertyModifiers(prop); got != IS_PRIVATE|IS_READONLY {
+		t.Errorf("PropertyModifiers() = %d", got)
+	}
+
+	class := types.NewClassEntry("C")
+	class.IsAbstract = true
+	if got := ClassModifiers(class); got != IS_EXPLICIT_ABSTRACT {
+		t.Errorf("ClassModifiers() = %d", got)
+	}
+
+	tests := []struct {
+		modifiers int64
+		want      []string
+	}{
+		{IS_PUBLIC | IS_STATIC, []string{"public", "static"}},
+		{IS_ABSTRACT | IS_PROTECTED, []string{"abstract", "protected"}},
+		{IS_FINAL | IS_PRIVATE | IS_READONLY, []string{"final", "private", "readonly"}},
+		{0, nil},
+	}
+	for _, tt := range tests {
+		if got := ModifierNames(tt.modifiers); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ModifierNames(%d) = %v, want %v", tt.modifiers, got, tt.want)
+		}
+	}
+}
+
+func TestMembers(t *testing.T) {
+	parent := types.NewClassEntry("Base")
+	parent.Methods["run"] = &types.MethodDef{Name: "run"}
+	parent.Methods["secret"] = &types.MethodDef{Name: "secret", Visibility: types.VisibilityPrivate}
+	parent.Constants["A"] = &types.ClassConstant{Name: "A", Value: types.NewInt(1)}
+
+	child := types.NewClassEntry("Child")
+	child.Methods["run"] = &types.MethodDef{Name: "run", DeclaringClass: "Child"}
+	child.Methods["boot"] = &types.MethodDef{Name: "boot"}
+	child.Constants["B"] = &types.ClassConstant{Name: "B", Value: types.NewInt(2)}
+	child.ParentClass = parent
+
+	var names []string
+	for _, method := range Methods(child) {
+		names = append(names, method.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"boot", "run", "secret"}) {
+		t.Errorf("Methods() = %v", names)
+	}
+	if method, ok := FindMethod(child, "RUN"); !ok || method.DeclaringClass != "Child" {
+		t.Error("Expected FindMethod to find the overriding run() case-insensitively")
+	}
+	if _, ok := FindMethod(child, "missing"); ok {
+		t.Error("Expected no missing() method")
+	}
+	if constants := Constants(child); len(constants) != 2 || constants[0].Name != "A" {
+		t.Errorf("Constants() = %v", constants)
+	}
+
+	params := []*types.ParameterDef{{Name: "a"}, {Name: "b", HasDefault: true}, {Name: "c"}, {Name: "d", HasDefault: true}}
+	if got := RequiredParameters(params); got != 3 {
+		t.Errorf("RequiredParameters() = %d, want 3", got)
+	}
+}
+
+func TestParseType(t *testing.T) {
+	tests := []struct {
+		decl string
+		want NamedType
+	}{
+		{"int", NamedType{Name: "int", Builtin: true}},
+		{"?String", NamedType{Name: "string", AllowsNull: true, Builtin: true}},
+		{"mixed", NamedType{Name: "mixed", AllowsNull: true, Builtin: true}},
+		{"\\App\\User", NamedType{Name: "App\\User"}},
+		{"?Foo", NamedType{Name: "Foo", AllowsNull: true}},
+	}
+	for _, tt := range tests {
+		got, ok := ParseType(tt.decl)
+		if !ok || got != tt.want {
+			t.Errorf("ParseType(%q) = %+v, want %+v", tt.decl, got, tt.want)
+		}
+	}
+	if _, ok := ParseType(""); ok {
+		t.Error("Expected no type for an empty declaration")
+	}
+	if got, _ := ParseType("?int"); got.String() != "?int" {
+		t.Errorf("String() = %q", got.String())
+	}
+}
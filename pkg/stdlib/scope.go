@@ -0,0 +1,17 @@
+package stdlib
+
+import "github.com/krizos/php-go/pkg/types"
+
+// Scope gives standard library functions access to the variables of the
+// function calling them, as compact() and extract() need. The VM
+// implements it for the calling frame.
+type Scope interface {
+	// LookupVariable returns the value of a variable; false if undefined
+	LookupVariable(name string) (*types.Value, bool)
+	// AssignVariable assigns a variable, creating it if needed
+	AssignVariable(name string, value *types.Value) error
+	// BindVariable makes a variable a reference to ref
+	BindVariable(name string, ref *types.Value) error
+	// Warning emits a warning attributed to the calling code
+	Warning(message string)
+}
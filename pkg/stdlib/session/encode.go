@@ -0,0 +1,86 @@
+package session
+
+import (
+	"strings"
+
+	varfuncs "github.com/krizos/php-go/pkg/stdlib/var"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Session Serialization
+// ============================================================================
+
+// delimiter separates a variable name from its serialized value in the
+// "php" serialize handler's format: name|value name|value ...
+const delimiter = '|'
+
+// serializer returns the serializer of the session values
+func (s *Session) serializer() *varfuncs.Serializer {
+	if s.Serializer != nil {
+		return s.Serializer
+	}
+	return &varfuncs.Serializer{}
+}
+
+// Encode returns the session data in the "php" format, as
+// session_encode() does. Integer keys are skipped; false for a name
+// containing the "|" delimiter.
+func (s *Session) Encode(data *types.Array) (string, bool, error) {
+	var out strings.Builder
+	encoder := s.serializer().NewEncoder()
+	ok := true
+	var err error
+	data.Each(func(key, value *types.Value) bool {
+		if key.IsInt() {
+			return true
+		}
+		name := key.ToString()
+		if strings.IndexByte(name, delimiter) >= 0 {
+			ok = false
+			return false
+		}
+		var encoded string
+		if encoded, err = encoder.Encode(value); err != nil {
+			return false
+		}
+		out.WriteString(name)
+		out.WriteByte(delimiter)
+		out.WriteString(encoded)
+		return true
+	})
+	if !ok || err != nil {
+		return "", false, err
+	}
+	return out.String(), true, nil
+}
+
+// Decode sets the variables of data in the "php" format in vars, as
+// session_decode() does. Malformed data destroys the session: false
+// after a warning.
+func (s *Session) Decode(data string, vars *types.Array) (bool, error) {
+	decoder := s.serializer().NewDecoder(data)
+	for pos := 0; pos < len(data); {
+		end := strings.IndexByte(data[pos:], delimiter)
+		if end < 0 {
+			break
+		}
+		name := data[pos : pos+end]
+		value, next, err := decoder.Decode(pos + end + 1)
+		if err != nil {
+			if _, malformed := err.(*varfuncs.UnserializeError); !malformed {
+				return false, err
+			}
+			if s.Status == PHP_SESSION_ACTIVE {
+				if _, err := s.Destroy(); err != nil {
+					return false, err
+				}
+			}
+			s.warn("Failed to decode session object. Session has been destroyed")
+			return false, nil
+		}
+		vars.Set(types.NewString(name), value)
+		pos = next
+	}
+	return true, nil
+}
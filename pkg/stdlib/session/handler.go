@@ -0,0 +1,167 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Save Handlers
+// ============================================================================
+
+// ErrFailed is returned by a save handler for an operation that failed,
+// as a user handler returning false does
+var ErrFailed = errors.New("session save handler failed")
+
+// ErrInvalidID is returned for a session ID the handler cannot store
+var ErrInvalidID = errors.New(`The session id is too long or contains illegal characters, valid characters are a-z, A-Z, 0-9 and "-,"`)
+
+// SaveHandler stores the session data, as a session module or a handler
+// set by session_set_save_handler() does. Any error other than ErrFailed
+// and ErrInvalidID, such as an exception thrown by a user handler, is
+// passed on to the script.
+type SaveHandler interface {
+	Open(savePath, name string) error
+	Close() error
+	Read(id string) (string, error)
+	Write(id, data string) error
+	Destroy(id string) error
+	GC(maxLifetime int64) (int64, error)
+}
+
+// IDCreator is implemented by handlers creating their own session IDs
+type IDCreator interface {
+	CreateID() (string, error)
+}
+
+// TimestampUpdater is implemented by handlers that can refresh a session
+// whose data did not change without writing it (session.lazy_write)
+type TimestampUpdater interface {
+	UpdateTimestamp(id, data string) error
+}
+
+// handlerName is the module name of a handler in warnings
+func handlerName(h SaveHandler) string {
+	if _, ok := h.(*FileHandler); ok {
+		return "files"
+	}
+	return "user"
+}
+
+// FileHandler is the files module: each session is stored in a file
+// named sess_<id> in the save path, or the system temporary directory
+// when the save path is empty
+type FileHandler struct {
+	dir string
+}
+
+// NewFileHandler returns the files save handler
+func NewFileHandler() *FileHandler {
+	return &FileHandler{}
+}
+
+// Open selects the directory of the session files. A save path of the
+// form "N;/path" (with N subdirectory levels in PHP) uses /path.
+func (h *FileHandler) Open(savePath, name string) error {
+	if i := strings.LastIndexByte(savePath, ';'); i >= 0 {
+		savePath = savePath[i+1:]
+	}
+	if savePath == "" {
+		savePath = os.TempDir()
+	}
+	if info, err := os.Stat(savePath); err != nil || !info.IsDir() {
+		return ErrFailed
+	}
+	h.dir = savePath
+	return nil
+}
+
+// Close releases the directory
+func (h *FileHandler) Close() error {
+	return nil
+}
+
+// Read returns the data of a session, empty for a new one
+func (h *FileHandler) Read(id string) (string, error) {
+	path, err := h.path(id)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", ErrFailed
+	}
+	return string(data), nil
+}
+
+// Write stores the data of a session
+func (h *FileHandler) Write(id, data string) error {
+	path, err := h.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		return ErrFailed
+	}
+	return nil
+}
+
+// UpdateTimestamp marks an unchanged session as used now
+func (h *FileHandler) UpdateTimestamp(id, data string) error {
+	path, err := h.path(id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		return h.Write(id, data)
+	}
+	return nil
+}
+
+// Destroy removes the file of a session
+func (h *FileHandler) Destroy(id string) error {
+	path, err := h.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return ErrFailed
+	}
+	return nil
+}
+
+// GC removes the session files not modified for maxLifetime seconds and
+// returns how many were removed
+func (h *FileHandler) GC(maxLifetime int64) (int64, error) {
+	paths, err := filepath.Glob(filepath.Join(h.dir, "sess_*"))
+	if err != nil {
+		return 0, ErrFailed
+	}
+	expired := time.Now().Add(-time.Duration(maxLifetime) * time.Second)
+	var removed int64
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Before(expired) {
+			continue
+		}
+		if os.Remove(path) == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// path returns the file of a session
+func (h *FileHandler) path(id string) (string, error) {
+	if !ValidID(id) {
+		return "", ErrInvalidID
+	}
+	return filepath.Join(h.dir, "sess_"+id), nil
+}
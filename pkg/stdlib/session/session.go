@@ -0,0 +1,512 @@
+// Package session implements PHP's session handling: the session
+// lifecycle behind session_start() and the related functions, the "php"
+// serialization of $_SESSION and the save handlers storing it.
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	varfuncs "github.com/krizos/php-go/pkg/stdlib/var"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// Session statuses, the values of session_status()
+const (
+	PHP_SESSION_DISABLED = 0
+	PHP_SESSION_NONE     = 1
+	PHP_SESSION_ACTIVE   = 2
+)
+
+// Constants returns the session constants
+func Constants() map[string]*types.Value {
+	return map[string]*types.Value{
+		"PHP_SESSION_DISABLED": types.NewInt(PHP_SESSION_DISABLED),
+		"PHP_SESSION_NONE":     types.NewInt(PHP_SESSION_NONE),
+		"PHP_SESSION_ACTIVE":   types.NewInt(PHP_SESSION_ACTIVE),
+	}
+}
+
+// Error is a session failure thrown as a PHP exception of Class
+type Error struct {
+	Class   string
+	Message string
+}
+
+// Error returns the message
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// CookieParams are the attributes of the session cookie
+// (session_set_cookie_params())
+type CookieParams struct {
+	Lifetime int64 // Seconds the cookie lasts, 0 until the browser closes
+	Path     string
+	Domain   string
+	Secure   bool
+	HTTPOnly bool
+	SameSite string
+}
+
+// Array returns the parameters as session_get_cookie_params() does
+func (c CookieParams) Array() *types.Array {
+	arr := types.NewEmptyArray()
+	arr.Set(types.NewString("lifetime"), types.NewInt(c.Lifetime))
+	arr.Set(types.NewString("path"), types.NewString(c.Path))
+	arr.Set(types.NewString("domain"), types.NewString(c.Domain))
+	arr.Set(types.NewString("secure"), types.NewBool(c.Secure))
+	arr.Set(types.NewString("httponly"), types.NewBool(c.HTTPOnly))
+	arr.Set(types.NewString("samesite"), types.NewString(c.SameSite))
+	return arr
+}
+
+// Session is the session of a request. Its settings default to PHP's
+// session.* ini defaults; the hooks are optional.
+type Session struct {
+	Name          string      // session.name, the cookie name
+	ID            string      // Session ID, empty before one is set or created
+	SavePath      string      // session.save_path
+	Status        int         // PHP_SESSION_NONE or PHP_SESSION_ACTIVE
+	Handler       SaveHandler // Handler storing the data
+	Default       SaveHandler // The files handler, which SessionHandler wraps
+	Cookie        CookieParams
+	UseCookies    bool  // session.use_cookies
+	LazyWrite     bool  // session.lazy_write: unchanged data is not written
+	GCMaxLifetime int64 // session.gc_maxlifetime, in seconds
+	GCProbability int64 // session.gc_probability
+	GCDivisor     int64 // session.gc_divisor
+
+	// Serializer converts the values of $_SESSION (nil for the default)
+	Serializer *varfuncs.Serializer
+	// SendCookie emits a response header line, as the SAPI layer does
+	SendCookie func(header string)
+	// Warning reports a warning
+	Warning func(message string)
+
+	read   string // Data read when the session started, for lazy_write
+	loaded bool   // Whether read holds the stored data
+}
+
+// New returns a session with the default settings and the files handler
+func New() *Session {
+	files := NewFileHandler()
+	return &Session{
+		Name:          "PHPSESSID",
+		Status:        PHP_SESSION_NONE,
+		Handler:       files,
+		Default:       files,
+		Cookie:        CookieParams{Path: "/"},
+		UseCookies:    true,
+		LazyWrite:     true,
+		GCMaxLifetime: 1440,
+		GCProbability: 1,
+		GCDivisor:     100,
+	}
+}
+
+// warn reports a warning
+func (s *Session) warn(format string, args ...interface{}) {
+	if s.Warning != nil {
+		s.Warning(fmt.Sprintf(format, args...))
+	}
+}
+
+// handlerError reports a handler failure: false returns and invalid IDs
+// give nil after any warning, other errors (exceptions) are returned
+func (s *Session) handlerError(err error) error {
+	if errors.Is(err, ErrInvalidID) {
+		s.warn("%s", ErrInvalidID.Error())
+		return nil
+	}
+	if errors.Is(err, ErrFailed) {
+		return nil
+	}
+	return err
+}
+
+// module describes the handler in warnings
+func (s *Session) module() string {
+	return handlerName(s.Handler)
+}
+
+// ============================================================================
+// Session IDs
+// ============================================================================
+
+// ValidID reports whether a session ID is made of the characters a-z,
+// A-Z, 0-9, "," and "-" and at most 256 of them
+func ValidID(id string) bool {
+	return id != "" && len(id) <= 256 && validIDChars(id)
+}
+
+func validIDChars(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == ',' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// RandomID returns a new session ID of 32 hexadecimal characters (the
+// default session.sid_length and session.sid_bits_per_character)
+func RandomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newID creates a session ID with the handler, if it creates its own
+func (s *Session) newID() (string, error) {
+	if creator, ok := s.Handler.(IDCreator); ok {
+		return creator.CreateID()
+	}
+	return RandomID()
+}
+
+// CreateID returns a new session ID starting with prefix, as
+// session_create_id() does; false after a warning for an invalid prefix
+func (s *Session) CreateID(prefix string) (string, bool, error) {
+	if !validIDChars(prefix) {
+		s.warn(`Prefix cannot contain special characters. Only the A-Z, a-z, 0-9, "-", and "," characters are allowed`)
+		return "", false, nil
+	}
+	id, err := s.newID()
+	if err != nil {
+		if err = s.handlerError(err); err == nil {
+			s.warn("Failed to create new ID")
+		}
+		return "", false, err
+	}
+	return prefix + id, true, nil
+}
+
+// ============================================================================
+// Cookies
+// ============================================================================
+
+// CookieHeader returns the Set-Cookie header of the session cookie
+func (s *Session) CookieHeader() string {
+	var header strings.Builder
+	header.WriteString("Set-Cookie: ")
+	header.WriteString(s.Name)
+	header.WriteByte('=')
+	header.WriteString(url.QueryEscape(s.ID))
+	if s.Cookie.Lifetime > 0 {
+		expires := time.Now().Add(time.Duration(s.Cookie.Lifetime) * time.Second).UTC()
+		fmt.Fprintf(&header, "; expires=%s; Max-Age=%d", expires.Format("Mon, 02 Jan 2006 15:04:05 GMT"), s.Cookie.Lifetime)
+	}
+	if s.Cookie.Path != "" {
+		header.WriteString("; path=" + s.Cookie.Path)
+	}
+	if s.Cookie.Domain != "" {
+		header.WriteString("; domain=" + s.Cookie.Domain)
+	}
+	if s.Cookie.Secure {
+		header.WriteString("; secure")
+	}
+	if s.Cookie.HTTPOnly {
+		header.WriteString("; HttpOnly")
+	}
+	if s.Cookie.SameSite != "" {
+		header.WriteString("; SameSite=" + s.Cookie.SameSite)
+	}
+	return header.String()
+}
+
+// sendCookie emits the session cookie
+func (s *Session) sendCookie() {
+	if s.UseCookies && s.SendCookie != nil {
+		s.SendCookie(s.CookieHeader())
+	}
+}
+
+// ============================================================================
+// Options
+// ============================================================================
+
+// SetOption changes a session.* setting by its name without the prefix,
+// as session_start()'s options do; false for an unknown setting
+func (s *Session) SetOption(name string, value *types.Value) bool {
+	switch name {
+	case "name":
+		s.Name = value.ToString()
+	case "save_path":
+		s.SavePath = value.ToString()
+	case "cookie_lifetime":
+		s.Cookie.Lifetime = value.ToInt()
+	case "cookie_path":
+		s.Cookie.Path = value.ToString()
+	case "cookie_domain":
+		s.Cookie.Domain = value.ToString()
+	case "cookie_secure":
+		s.Cookie.Secure = value.ToBool()
+	case "cookie_httponly":
+		s.Cookie.HTTPOnly = value.ToBool()
+	case "cookie_samesite":
+		s.Cookie.SameSite = value.ToString()
+	case "use_cookies":
+		s.UseCookies = value.ToBool()
+	case "lazy_write":
+		s.LazyWrite = value.ToBool()
+	case "gc_maxlifetime":
+		s.GCMaxLifetime = value.ToInt()
+	case "gc_probability":
+		s.GCProbability = value.ToInt()
+	case "gc_divisor":
+		s.GCDivisor = value.ToInt()
+	default:
+		return false
+	}
+	return true
+}
+
+// ============================================================================
+// Lifecycle
+// ============================================================================
+
+// Start starts the session and returns its data, as session_start()
+// does. The ID set before (session_id()) is used, else the one of the
+// request's session cookie, else a new one is created and its cookie
+// sent. False after a warning when the session cannot be started.
+func (s *Session) Start(cookieID string) (*types.Array, bool, error) {
+	sendCookie := true
+	if s.ID == "" && cookieID != "" && s.UseCookies {
+		s.ID, sendCookie = cookieID, false
+	}
+
+	if err := s.Handler.Open(s.SavePath, s.Name); err != nil {
+		if err = s.handlerError(err); err != nil {
+			return nil, false, err
+		}
+		s.warn("Failed to initialize storage module: %s (path: %s)", s.module(), s.SavePath)
+		return nil, false, nil
+	}
+
+	if s.ID == "" {
+		id, err := s.newID()
+		if err != nil {
+			s.Handler.Close()
+			if err = s.handlerError(err); err != nil {
+				return nil, false, err
+			}
+			s.warn("Failed to create session ID: %s (path: %s)", s.module(), s.SavePath)
+			return nil, false, nil
+		}
+		s.ID, sendCookie = id, true
+	}
+	if sendCookie {
+		s.sendCookie()
+	}
+
+	s.Status = PHP_SESSION_ACTIVE
+	data, ok, err := s.load()
+	if !ok || err != nil {
+		return nil, false, err
+	}
+	s.collect()
+	return data, true, nil
+}
+
+// load reads and decodes the session data
+func (s *Session) load() (*types.Array, bool, error) {
+	raw, err := s.Handler.Read(s.ID)
+	if err != nil {
+		s.Handler.Close()
+		s.Status = PHP_SESSION_NONE
+		if err = s.handlerError(err); err != nil {
+			return nil, false, err
+		}
+		s.warn("Failed to read session data: %s (path: %s)", s.module(), s.SavePath)
+		return nil, false, nil
+	}
+	s.read, s.loaded = raw, true
+
+	data := types.NewEmptyArray()
+	ok, err := s.Decode(raw, data)
+	if !ok || err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// collect runs the garbage collection with the probability
+// gc_probability/gc_divisor
+func (s *Session) collect() {
+	if s.GCProbability <= 0 || s.GCDivisor <= 0 {
+		return
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(s.GCDivisor))
+	if err == nil && n.Int64() < s.GCProbability {
+		s.Handler.GC(s.GCMaxLifetime)
+	}
+}
+
+// WriteClose stores the session data and closes the session, as
+// session_write_close() does; false if no session is active
+func (s *Session) WriteClose(data *types.Array) (bool, error) {
+	if s.Status != PHP_SESSION_ACTIVE {
+		return false, nil
+	}
+	s.Status = PHP_SESSION_NONE
+	encoded, ok, err := s.Encode(data)
+	if err != nil {
+		s.Handler.Close()
+		return false, err
+	}
+	if !ok {
+		encoded = ""
+	}
+
+	if s.LazyWrite && s.loaded && encoded == s.read {
+		if updater, ok := s.Handler.(TimestampUpdater); ok {
+			err = updater.UpdateTimestamp(s.ID, encoded)
+		} else {
+			err = s.Handler.Write(s.ID, encoded)
+		}
+	} else {
+		err = s.Handler.Write(s.ID, encoded)
+	}
+	if err != nil {
+		if err = s.handlerError(err); err != nil {
+			s.Handler.Close()
+			return false, err
+		}
+		if handlerName(s.Handler) == "files" {
+			s.warn("Failed to write session data (files). Please verify that the current setting of session.save_path is correct (%s)", s.SavePath)
+		} else {
+			s.warn("Failed to write session data using user defined save handler. (session.save_path: %s)", s.SavePath)
+		}
+	}
+	s.loaded = false
+	return true, s.Handler.Close()
+}
+
+// Abort closes the session without storing its data; false if no
+// session is active
+func (s *Session) Abort() (bool, error) {
+	if s.Status != PHP_SESSION_ACTIVE {
+		return false, nil
+	}
+	s.Status, s.loaded = PHP_SESSION_NONE, false
+	return true, s.handlerError(s.Handler.Close())
+}
+
+// Reset reads the stored data of the active session again, dropping
+// its changes, as session_reset() does
+func (s *Session) Reset() (*types.Array, bool, error) {
+	if s.Status != PHP_SESSION_ACTIVE {
+		return nil, false, nil
+	}
+	return s.load()
+}
+
+// Destroy removes the stored data of the active session and closes it,
+// as session_destroy() does
+func (s *Session) Destroy() (bool, error) {
+	if s.Status != PHP_SESSION_ACTIVE {
+		s.warn("Trying to destroy uninitialized session")
+		return false, nil
+	}
+	result := true
+	if err := s.Handler.Destroy(s.ID); err != nil {
+		if err = s.handlerError(err); err != nil {
+			s.Status = PHP_SESSION_NONE
+			return false, err
+		}
+		s.warn("Session object destruction failed")
+		result = false
+	}
+	s.Status, s.ID, s.loaded = PHP_SESSION_NONE, "", false
+	if err := s.handlerError(s.Handler.Close()); err != nil {
+		return false, err
+	}
+	return result, nil
+}
+
+// RegenerateID moves the active session to a new ID and sends its
+// cookie, as session_regenerate_id() does. The data stays stored under
+// the old ID, unless deleteOld removes it.
+func (s *Session) RegenerateID(deleteOld bool, data *types.Array) (bool, error) {
+	if s.Status != PHP_SESSION_ACTIVE {
+		s.warn("Session ID cannot be regenerated when there is no active session")
+		return false, nil
+	}
+
+	if deleteOld {
+		if err := s.Handler.Destroy(s.ID); err != nil {
+			s.Handler.Close()
+			s.Status = PHP_SESSION_NONE
+			if err = s.handlerError(err); err != nil {
+				return false, err
+			}
+			s.warn("Session object destruction failed. ID: %s (path: %s)", s.module(), s.SavePath)
+			return false, nil
+		}
+	} else {
+		encoded, _, err := s.Encode(data)
+		if err == nil {
+			err = s.Handler.Write(s.ID, encoded)
+		}
+		if err != nil {
+			s.Handler.Close()
+			s.Status = PHP_SESSION_NONE
+			if err = s.handlerError(err); err != nil {
+				return false, err
+			}
+			s.warn("Session write failed. ID: %s (path: %s)", s.module(), s.SavePath)
+			return false, nil
+		}
+	}
+	if err := s.handlerError(s.Handler.Close()); err != nil {
+		s.Status = PHP_SESSION_NONE
+		return false, err
+	}
+
+	fail := func(err error, format string) (bool, error) {
+		s.Status = PHP_SESSION_NONE
+		if err = s.handlerError(err); err != nil {
+			return false, err
+		}
+		return false, &Error{Class: "Error", Message: fmt.Sprintf(format, s.module(), s.SavePath)}
+	}
+	if err := s.Handler.Open(s.SavePath, s.Name); err != nil {
+		return fail(err, "Failed to open session: %s (path: %s)")
+	}
+	id, err := s.newID()
+	if err != nil {
+		return fail(err, "Failed to create new session ID: %s (path: %s)")
+	}
+	s.ID = id
+	if _, err := s.Handler.Read(id); err != nil {
+		return fail(err, "Failed to create(read) session ID: %s (path: %s)")
+	}
+	// The data of the new ID differs from what is stored, so it is written
+	s.loaded = false
+	s.sendCookie()
+	return true, nil
+}
+
+// GC removes the expired sessions of the handler, as session_gc() does,
+// and returns how many were removed; false on failure
+func (s *Session) GC() (*types.Value, error) {
+	if s.Status != PHP_SESSION_ACTIVE {
+		s.warn("Session cannot be garbage collected when there is no active session")
+		return types.NewBool(false), nil
+	}
+	n, err := s.Handler.GC(s.GCMaxLifetime)
+	if err != nil {
+		return types.NewBool(false), s.handlerError(err)
+	}
+	return types.NewInt(n), nil
+}
@@ -0,0 +1,297 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// memoryHandler stores sessions in a map and records the calls made
+type memoryHandler struct {
+	data  map[string]string
+	calls []string
+	fail  string // Operation returning ErrFailed
+}
+
+func newMemoryHandler() *memoryHandler {
+	return &memoryHandler{data: make(map[string]string)}
+}
+
+func (h *memoryHandler) call(op string) error {
+	h.calls = append(h.calls, op)
+	if op == h.fail {
+		return ErrFailed
+	}
+	return nil
+}
+
+func (h *memoryHandler) Open(savePath, name string) error { return h.call("open") }
+func (h *memoryHandler) Close() error                     { return h.call("close") }
+func (h *memoryHandler) Read(id string) (string, error)   { return h.data[id], h.call("read") }
+func (h *memoryHandler) Destroy(id string) error {
+	delete(h.data, id)
+	return h.call("destroy")
+}
+func (h *memoryHandler) GC(maxLifetime int64) (int64, error) { return 0, h.call("gc") }
+func (h *memoryHandler) Write(id, data string) error {
+	h.data[id] = data
+	return h.call("write")
+}
+
+// newTestSession returns a session without garbage collection that
+// records its warnings and cookies
+func newTestSession(handler SaveHandler) (*Session, *[]string, *[]string) {
+	var warnings, cookies []string
+	s := New()
+	s.GCProbability = 0
+	s.Warning = func(message string) { warnings = append(warnings, message) }
+	s.SendCookie = func(header string) { cookies = append(cookies, header) }
+	if handler != nil {
+		s.Handler = handler
+	}
+	return s, &warnings, &cookies
+}
+
+func vars(pairs ...any) *types.Array {
+	arr := types.NewEmptyArray()
+	for i := 0; i < len(pairs); i += 2 {
+		var value *types.Value
+		switch v := pairs[i+1].(type) {
+		case string:
+			value = types.NewString(v)
+		case int:
+			value = types.NewInt(int64(v))
+		case *types.Value:
+			value = v
+		}
+		arr.Set(types.NewString(pairs[i].(string)), value)
+	}
+	return arr
+}
+
+func TestEncodeDecode(t *testing.T) {
+	s := New()
+	encoded, ok, err := s.Encode(vars("user", "bob", "count", 3, "list", types.NewArray(vars("a", 1))))
+	want := `user|s:3:"bob";count|i:3;list|a:1:{s:1:"a";i:1;}`
+	if err != nil || !ok || encoded != want {
+		t.Fatalf("Encode = %q, %v, %v; want %q", encoded, ok, err, want)
+	}
+
+	decoded := types.NewEmptyArray()
+	if ok, err := s.Decode(want, decoded); !ok || err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if user, _ := decoded.Get(types.NewString("user")); user.ToString() != "bob" || decoded.Len() != 3 {
+		t.Errorf("Decode = %v", decoded)
+	}
+
+	if _, ok, _ := s.Encode(vars("a|b", 1)); ok {
+		t.Errorf("names containing | cannot be encoded")
+	}
+}
+
+func TestDecodeMalformedDestroysSession(t *testing.T) {
+	handler := newMemoryHandler()
+	handler.data["abc"] = `user|s:9:"bob";`
+	s, warnings, _ := newTestSession(handler)
+	s.ID = "abc"
+
+	data, ok, err := s.Start("")
+	if ok || err != nil || data != nil {
+		t.Fatalf("Start with malformed data = %v, %v, %v", data, ok, err)
+	}
+	if s.Status != PHP_SESSION_NONE || len(*warnings) != 1 || (*warnings)[0] != "Failed to decode session object. Session has been destroyed" {
+		t.Errorf("status %d, warnings %q", s.Status, *warnings)
+	}
+	if _, ok := handler.data["abc"]; ok {
+		t.Errorf("the malformed session should be destroyed")
+	}
+}
+
+func TestFileHandlerLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	s, _, cookies := newTestSession(nil)
+	s.SavePath = dir
+
+	data, ok, err := s.Start("")
+	if !ok || err != nil || data.Len() != 0 {
+		t.Fatalf("Start = %v, %v, %v", data, ok, err)
+	}
+	if !ValidID(s.ID) || len(s.ID) != 32 {
+		t.Errorf("new ID %q", s.ID)
+	}
+	if len(*cookies) != 1 || (*cookies)[0] != "Set-Cookie: PHPSESSID="+s.ID+"; path=/" {
+		t.Errorf("cookies = %q", *cookies)
+	}
+
+	data.Set(types.NewString("n"), types.NewInt(1))
+	if ok, err := s.WriteClose(data); !ok || err != nil {
+		t.Fatalf("WriteClose = %v, %v", ok, err)
+	}
+	stored, err := os.ReadFile(filepath.Join(dir, "sess_"+s.ID))
+	if err != nil || string(stored) != "n|i:1;" {
+		t.Fatalf("stored %q, %v", stored, err)
+	}
+
+	// The next request sends the cookie back
+	next, _, cookies := newTestSession(nil)
+	next.SavePath = dir
+	data, ok, _ = next.Start(s.ID)
+	if n, _ := data.Get(types.NewString("n")); !ok || n.ToInt() != 1 {
+		t.Errorf("data of the cookie's session = %v", data)
+	}
+	if len(*cookies) != 0 {
+		t.Errorf("the cookie of a known session is not sent again: %q", *cookies)
+	}
+
+	if ok, _ := next.Destroy(); !ok || next.ID != "" || next.Status != PHP_SESSION_NONE {
+		t.Errorf("Destroy: ok %v, ID %q, status %d", ok, next.ID, next.Status)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sess_"+s.ID)); !os.IsNotExist(err) {
+		t.Errorf("the session file should be removed")
+	}
+}
+
+func TestFileHandlerRejectsInvalidID(t *testing.T) {
+	s, warnings, _ := newTestSession(nil)
+	s.SavePath = t.TempDir()
+	s.ID = "../etc/passwd"
+	if _, ok, err := s.Start(""); ok || err != nil {
+		t.Fatalf("Start with an invalid ID = %v, %v", ok, err)
+	}
+	want := []string{ErrInvalidID.Error(), "Failed to read session data: files (path: " + s.SavePath + ")"}
+	if strings.Join(*warnings, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings = %q", *warnings)
+	}
+}
+
+func TestFileHandlerGC(t *testing.T) {
+	dir := t.TempDir()
+	h := NewFileHandler()
+	if err := h.Open(dir, "PHPSESSID"); err != nil {
+		t.Fatal(err)
+	}
+	h.Write("old", "")
+	h.Write("new", "")
+	expired := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, "sess_old"), expired, expired)
+	if n, err := h.GC(1440); n != 1 || err != nil {
+		t.Errorf("GC = %d, %v", n, err)
+	}
+	if err := h.Open(filepath.Join(dir, "missing"), "PHPSESSID"); err != ErrFailed {
+		t.Errorf("opening a missing directory: %v", err)
+	}
+}
+
+// touchingHandler is a memoryHandler updating timestamps without writing
+type touchingHandler struct {
+	*memoryHandler
+}
+
+func (h touchingHandler) UpdateTimestamp(id, data string) error { return h.call("touch") }
+
+func TestLazyWrite(t *testing.T) {
+	handler := newMemoryHandler()
+	handler.data["abc"] = "n|i:1;"
+	s, _, _ := newTestSession(touchingHandler{handler})
+	s.ID = "abc"
+
+	data, _, _ := s.Start("")
+	s.WriteClose(data)
+	if calls := strings.Join(handler.calls, ","); calls != "open,read,touch,close" {
+		t.Errorf("unchanged data should not be written: %v", calls)
+	}
+
+	// Handlers without UpdateTimestamp write the data
+	s.Handler = handler
+	handler.calls = nil
+	data, _, _ = s.Start("")
+	s.WriteClose(data)
+	if calls := strings.Join(handler.calls, ","); calls != "open,read,write,close" {
+		t.Errorf("calls = %v", calls)
+	}
+
+	data, _, _ = s.Start("")
+	data.Set(types.NewString("n"), types.NewInt(2))
+	s.WriteClose(data)
+	if handler.data["abc"] != "n|i:2;" {
+		t.Errorf("changed data should be written, stored %q", handler.data["abc"])
+	}
+}
+
+func TestRegenerateID(t *testing.T) {
+	handler := newMemoryHandler()
+	s, warnings, cookies := newTestSession(handler)
+	if ok, _ := s.RegenerateID(false, nil); ok || len(*warnings) != 1 {
+		t.Errorf("regenerating without a session: %v, %q", ok, *warnings)
+	}
+
+	data, _, _ := s.Start("")
+	old := s.ID
+	data.Set(types.NewString("k"), types.NewString("v"))
+	if ok, err := s.RegenerateID(false, data); !ok || err != nil || s.ID == old {
+		t.Fatalf("RegenerateID = %v, %v (ID %q)", ok, err, s.ID)
+	}
+	if handler.data[old] != `k|s:1:"v";` {
+		t.Errorf("the old session keeps its data: %q", handler.data[old])
+	}
+	if len(*cookies) != 2 || !strings.Contains((*cookies)[1], s.ID) {
+		t.Errorf("cookies = %q", *cookies)
+	}
+
+	renewed := s.ID
+	s.RegenerateID(true, data)
+	if _, ok := handler.data[renewed]; ok {
+		t.Errorf("deleteOld removes the previous session")
+	}
+	s.WriteClose(data)
+	if handler.data[s.ID] != `k|s:1:"v";` {
+		t.Errorf("the data is written under the new ID: %v", handler.data)
+	}
+}
+
+func TestHandlerFailures(t *testing.T) {
+	handler := newMemoryHandler()
+	handler.fail = "open"
+	s, warnings, _ := newTestSession(handler)
+	if _, ok, _ := s.Start(""); ok || (*warnings)[0] != "Failed to initialize storage module: user (path: )" {
+		t.Errorf("open failure: %v, %q", ok, *warnings)
+	}
+
+	handler.fail = "open"
+	s.Status = PHP_SESSION_ACTIVE
+	s.ID = "abc"
+	_, err := s.RegenerateID(false, types.NewEmptyArray())
+	if e, ok := err.(*Error); !ok || e.Class != "Error" || e.Message != "Failed to open session: user (path: )" {
+		t.Errorf("reopen failure: %v", err)
+	}
+}
+
+func TestCreateIDAndCookieParams(t *testing.T) {
+	s, warnings, _ := newTestSession(nil)
+	if id, ok, _ := s.CreateID("pre-"); !ok || !strings.HasPrefix(id, "pre-") || len(id) != 36 {
+		t.Errorf("CreateID('pre-') = %q, %v", id, ok)
+	}
+	if _, ok, _ := s.CreateID("a b"); ok || len(*warnings) != 1 {
+		t.Errorf("invalid prefix: %v, %q", ok, *warnings)
+	}
+
+	s.ID = "abc"
+	s.Cookie = CookieParams{Lifetime: 60, Path: "/app", Domain: "example.com", Secure: true, HTTPOnly: true, SameSite: "Lax"}
+	header := s.CookieHeader()
+	if !strings.HasPrefix(header, "Set-Cookie: PHPSESSID=abc; expires=") ||
+		!strings.HasSuffix(header, " GMT; Max-Age=60; path=/app; domain=example.com; secure; HttpOnly; SameSite=Lax") {
+		t.Errorf("CookieHeader = %q", header)
+	}
+
+	if !s.SetOption("cookie_lifetime", types.NewInt(5)) || s.Cookie.Lifetime != 5 {
+		t.Errorf("SetOption(cookie_lifetime)")
+	}
+	if s.SetOption("nope", types.NewInt(1)) {
+		t.Errorf("unknown options are rejected")
+	}
+}
@@ -0,0 +1,196 @@
+package string
+
+import (
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Quoted-Printable Encoding
+// ============================================================================
+
+// quotedPrintableLineLength is the longest encoded line before a soft
+// line break
+const quotedPrintableLineLength = 75
+
+// QuotedPrintableEncode encodes a string as quoted-printable (RFC 2045):
+// control characters, "=", bytes above 0x7f and spaces before a CR become
+// "=XX", CRLF line breaks are kept and longer lines are broken with soft
+// "=\r\n" breaks, never inside an encoded UTF-8 sequence
+// quoted_printable_encode(string $string): string
+func QuotedPrintableEncode(str *types.Value) *types.Value {
+	s := str.ToString()
+	var result strings.Builder
+	line := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+			result.WriteString("\r\n")
+			i++
+			line = 0
+			continue
+		}
+
+		if c < 0x20 || c >= 0x7f || c == '=' || (c == ' ' && i+1 < len(s) && s[i+1] == '\r') {
+			// A lead byte of UTF-8 needs room for its whole sequence
+			line += 3
+			var lineBreak bool
+			switch {
+			case c <= 0x7f:
+				lineBreak = line > quotedPrintableLineLength
+			case c <= 0xdf:
+				lineBreak = line+3 > quotedPrintableLineLength
+			case c <= 0xef:
+				lineBreak = line+6 > quotedPrintableLineLength
+			case c <= 0xf4:
+				lineBreak = line+9 > quotedPrintableLineLength
+			}
+			if lineBreak {
+				result.WriteString("=\r\n")
+				line = 3
+			}
+			result.WriteByte('=')
+			result.WriteByte(hexDigit(c >> 4))
+			result.WriteByte(hexDigit(c & 0xf))
+			continue
+		}
+
+		line++
+		if line > quotedPrintableLineLength {
+			result.WriteString("=\r\n")
+			line = 1
+		}
+		result.WriteByte(c)
+	}
+	return types.NewString(result.String())
+}
+
+// QuotedPrintableDecode decodes a quoted-printable string: "=XX" becomes
+// the byte XX and soft line breaks ("=" followed by optional blanks and a
+// line break, or the end of the string) are removed
+// quoted_printable_decode(string $string): string
+func QuotedPrintableDecode(str *types.Value) *types.Value {
+	s := str.ToString()
+	var result strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '=' {
+			result.WriteByte(s[i])
+			i++
+			continue
+		}
+		if i+2 < len(s) && unhex(s[i+1]) >= 0 && unhex(s[i+2]) >= 0 {
+			result.WriteByte(byte(unhex(s[i+1])<<4 | unhex(s[i+2])))
+			i += 3
+			continue
+		}
+
+		k := i + 1
+		for k < len(s) && (s[k] == ' ' || s[k] == '\t') {
+			k++
+		}
+		switch {
+		case k == len(s):
+			i = k
+		case s[k] == '\r' && k+1 < len(s) && s[k+1] == '\n':
+			i = k + 2
+		case s[k] == '\r' || s[k] == '\n':
+			i = k + 1
+		default:
+			result.WriteByte('=')
+			i++
+		}
+	}
+	return types.NewString(result.String())
+}
+
+// ============================================================================
+// Uuencoding
+// ============================================================================
+
+// uuLineLength is the number of bytes encoded on a full uuencoded line
+const uuLineLength = 45
+
+// uuEncode encodes six bits as a uuencode character; zero is a backtick
+func uuEncode(c byte) byte {
+	if c&077 == 0 {
+		return '`'
+	}
+	return c&077 + ' '
+}
+
+// uuDecode decodes a uuencode character to six bits
+func uuDecode(c byte) byte {
+	return (c - ' ') & 077
+}
+
+// ConvertUuencode uuencodes a string: lines of up to 45 bytes, each
+// prefixed with its length, as 4 characters per 3 bytes, followed by an
+// empty line. The empty string encodes to the empty string.
+// convert_uuencode(string $string): string
+func ConvertUuencode(str *types.Value) *types.Value {
+	s := str.ToString()
+	if s == "" {
+		return types.NewString("")
+	}
+
+	var result strings.Builder
+	for start := 0; start < len(s); start += uuLineLength {
+		end := min(start+uuLineLength, len(s))
+		result.WriteByte(uuEncode(byte(end - start)))
+		for i := start; i < end; i += 3 {
+			// Missing bytes of the last group are zeros
+			a, b, c := s[i], byte(0), byte(0)
+			if i+1 < end {
+				b = s[i+1]
+			}
+			if i+2 < end {
+				c = s[i+2]
+			}
+			result.WriteByte(uuEncode(a >> 2))
+			result.WriteByte(uuEncode(a<<4&060 | b>>4&017))
+			result.WriteByte(uuEncode(b<<2&074 | c>>6&03))
+			result.WriteByte(uuEncode(c & 077))
+		}
+		result.WriteByte('\n')
+	}
+	result.WriteString("`\n")
+	return types.NewString(result.String())
+}
+
+// ConvertUudecode decodes a uuencoded string; false if it is empty or
+// not valid uuencoded data (a line shorter than its length announces)
+// convert_uudecode(string $string): string|false
+func ConvertUudecode(str *types.Value) (*types.Value, bool) {
+	s := str.ToString()
+	if s == "" {
+		return types.NewBool(false), true
+	}
+
+	var result []byte
+	for i := 0; i < len(s); {
+		length := int(uuDecode(s[i]))
+		i++
+		if length == 0 {
+			break
+		}
+		// Each group of 4 characters decodes to 3 bytes
+		groups := (length + 2) / 3
+		if i+groups*4 > len(s) {
+			return types.NewBool(false), false
+		}
+		var line []byte
+		for g := 0; g < groups; g++ {
+			a, b, c, d := uuDecode(s[i]), uuDecode(s[i+1]), uuDecode(s[i+2]), uuDecode(s[i+3])
+			line = append(line, a<<2|b>>4, b<<4|c>>2, c<<6|d)
+			i += 4
+		}
+		result = append(result, line[:length]...)
+		if length < uuLineLength {
+			break
+		}
+		// Skip the line break
+		i++
+	}
+	return types.NewString(string(result)), true
+}
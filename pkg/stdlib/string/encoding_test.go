@@ -0,0 +1,62 @@
+package string
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestQuotedPrintable(t *testing.T) {
+	tests := []struct {
+		decoded, encoded string
+	}{
+		{"plain text", "plain text"},
+		{"a=b", "a=3Db"},
+		{"caf\xc3\xa9", "caf=C3=A9"},
+		{"line \r\nnext\ttab", "line=20\r\nnext=09tab"},
+	}
+	for _, tt := range tests {
+		if got := QuotedPrintableEncode(types.NewString(tt.decoded)).ToString(); got != tt.encoded {
+			t.Errorf("QuotedPrintableEncode(%q) = %q, want %q", tt.decoded, got, tt.encoded)
+		}
+		if got := QuotedPrintableDecode(types.NewString(tt.encoded)).ToString(); got != tt.decoded {
+			t.Errorf("QuotedPrintableDecode(%q) = %q, want %q", tt.encoded, got, tt.decoded)
+		}
+	}
+
+	long := strings.Repeat("x", 80)
+	encoded := QuotedPrintableEncode(types.NewString(long)).ToString()
+	if encoded != strings.Repeat("x", 75)+"=\r\n"+strings.Repeat("x", 5) {
+		t.Errorf("Expected a soft line break after 75 characters, got %q", encoded)
+	}
+	if decoded := QuotedPrintableDecode(types.NewString(encoded)).ToString(); decoded != long {
+		t.Errorf("Expected soft line breaks to be removed, got %q", decoded)
+	}
+
+	if got := QuotedPrintableDecode(types.NewString("a=\nb= \t\r\nc=zz=")).ToString(); got != "abc=zz" {
+		t.Errorf("Expected soft breaks removed and invalid escapes kept, got %q", got)
+	}
+}
+
+func TestUuencode(t *testing.T) {
+	data := "test\ntext text text\r\n"
+	encoded := ConvertUuencode(types.NewString(data)).ToString()
+	if expected := "5=&5S=`IT97AT('1E>'0@=&5X=`T*\n`\n"; encoded != expected {
+		t.Errorf("ConvertUuencode = %q, want %q", encoded, expected)
+	}
+
+	for _, s := range []string{data, "a", "ab", strings.Repeat("0123456789", 10)} {
+		decoded, ok := ConvertUudecode(ConvertUuencode(types.NewString(s)))
+		if !ok || decoded.ToString() != s {
+			t.Errorf("Expected %q to survive a round trip, got %v", s, decoded)
+		}
+	}
+
+	if got := ConvertUuencode(types.NewString("")).ToString(); got != "" {
+		t.Errorf("Expected the empty string to encode to itself, got %q", got)
+	}
+	if result, ok := ConvertUudecode(types.NewString("M0123")); ok || result.ToBool() {
+		t.Errorf("Expected truncated data to be invalid, got %v", result)
+	}
+}
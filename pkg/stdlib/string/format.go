@@ -0,0 +1,393 @@
+package string
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// printf Format Engine
+// ============================================================================
+
+// maxFormatNumber bounds argument numbers, widths and precisions
+const maxFormatNumber = math.MaxInt32
+
+// maxFloatPrecision is the highest precision honored for floats
+const maxFloatPrecision = 53
+
+// FormatError is a printf() format error. Class names the PHP exception
+// it is thrown as: ValueError or ArgumentCountError.
+type FormatError struct {
+	Class   string
+	Message string
+}
+
+func (e *FormatError) Error() string {
+	return e.Message
+}
+
+func valueError(format string, args ...interface{}) error {
+	return &FormatError{Class: "ValueError", Message: fmt.Sprintf(format, args...)}
+}
+
+// formatSpec is one parsed conversion specification:
+// %[argnum$][flags][width][.precision]specifier
+type formatSpec struct {
+	padding    byte
+	leftAlign  bool
+	alwaysSign bool
+	width      int
+	precision  int  // -1 when not given
+	shortest   bool // precision -1 given through .*
+	verb       byte
+}
+
+// MissingArgumentsError reports format arguments that were not supplied.
+// Required and Given count the value arguments; Extra is the number of
+// parameters before them (the format, a stream) included in the message.
+type MissingArgumentsError struct {
+	Required, Given, Extra int
+}
+
+func (e *MissingArgumentsError) Error() string {
+	return fmt.Sprintf("%d arguments are required, %d given", e.Required+e.Extra, e.Given+e.Extra)
+}
+
+// Format formats args according to a printf() format string. It supports
+// argument numbers (%2$s), the -, +, space, 0 and 'c (custom padding)
+// flags, widths and precisions (also taken from the arguments with *) and
+// the b, c, d, e, E, f, F, g, G, h, H, o, s, u, x and X conversions.
+// Missing arguments are reported as a *MissingArgumentsError (counting
+// the format as one extra parameter), malformed formats as a *FormatError.
+func Format(format string, args []*types.Value) (string, error) {
+	var result strings.Builder
+	next := 0       // next sequential argument
+	maxMissing := 0 // highest argument number without a value
+
+	arg := func(n int) *types.Value {
+		if n >= len(args) {
+			maxMissing = max(maxMissing, n+1)
+			return nil
+		}
+		return args[n].Deref()
+	}
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			result.WriteByte(format[i])
+			continue
+		}
+		i++
+		if i < len(format) && format[i] == '%' {
+			result.WriteByte('%')
+			continue
+		}
+
+		// Argument number
+		argnum := -1
+		if j, n, ok := scanFormatNumber(format, i); ok && j < len(format) && format[j] == '$' {
+			if n <= 0 || n >= maxFormatNumber {
+				return "", valueError("Argument number specifier must be greater than zero and less than %d", maxFormatNumber)
+			}
+			argnum, i = n-1, j+1
+		}
+
+		spec := formatSpec{padding: ' ', precision: -1}
+
+		// Flags
+	flags:
+		for ; i < len(format); i++ {
+			switch format[i] {
+			case ' ', '0':
+				spec.padding = format[i]
+			case '-':
+				spec.leftAlign = true
+			case '+':
+				spec.alwaysSign = true
+			case '\'':
+				if i+1 >= len(format) {
+					return "", valueError("Missing padding character")
+				}
+				i++
+				spec.padding = format[i]
+			default:
+				break flags
+			}
+		}
+
+		// Width
+		if i < len(format) && format[i] == '*' {
+			i++
+			width := arg(next)
+			next++
+			if width != nil {
+				if width.Type() != types.TypeInt {
+					return "", valueError("Width must be an integer")
+				}
+				if width.ToInt() < 0 || width.ToInt() >= maxFormatNumber {
+					return "", valueError("Width must be greater than or equal to zero and less than %d", maxFormatNumber)
+				}
+				spec.width = int(width.ToInt())
+			}
+		} else if j, n, ok := scanFormatNumber(format, i); ok {
+			if n >= maxFormatNumber {
+				return "", valueError("Width must be greater than or equal to zero and less than %d", maxFormatNumber)
+			}
+			spec.width, i = n, j
+		}
+
+		// Precision
+		if i < len(format) && format[i] == '.' {
+			i++
+			spec.precision = 0
+			if i < len(format) && format[i] == '*' {
+				i++
+				precision := arg(next)
+				next++
+				if precision != nil {
+					if precision.Type() != types.TypeInt {
+						return "", valueError("Precision must be an integer")
+					}
+					if precision.ToInt() < -1 || precision.ToInt() >= maxFormatNumber {
+						return "", valueError("Precision must be between -1 and %d", maxFormatNumber)
+					}
+					spec.precision = int(precision.ToInt())
+					spec.shortest = spec.precision == -1
+				}
+			} else if j, n, ok := scanFormatNumber(format, i); ok {
+				if n >= maxFormatNumber {
+					return "", valueError("Precision must be greater than or equal to zero and less than %d", maxFormatNumber)
+				}
+				spec.precision, i = n, j
+			}
+		}
+
+		// The C length modifier is accepted and ignored
+		if i < len(format) && format[i] == 'l' {
+			i++
+		}
+		if i >= len(format) {
+			return "", valueError("Missing format specifier at end of string")
+		}
+		spec.verb = format[i]
+		if spec.verb == '%' {
+			result.WriteByte('%')
+			continue
+		}
+
+		if argnum < 0 {
+			argnum = next
+			next++
+		}
+		value := arg(argnum)
+		if value == nil {
+			continue
+		}
+		if err := spec.format(&result, value); err != nil {
+			return "", err
+		}
+	}
+
+	if maxMissing > 0 {
+		return "", &MissingArgumentsError{Required: maxMissing, Given: len(args), Extra: 1}
+	}
+	return result.String(), nil
+}
+
+// scanFormatNumber scans a decimal number at format[i:]. It returns the
+// offset after it, its value (capped at maxFormatNumber) and whether any
+// digits were found.
+func scanFormatNumber(format string, i int) (int, int, bool) {
+	start, n := i, 0
+	for ; i < len(format) && format[i] >= '0' && format[i] <= '9'; i++ {
+		n = min(n*10+int(format[i]-'0'), maxFormatNumber)
+	}
+	return i, n, i > start
+}
+
+// format appends value converted according to the specification
+func (spec formatSpec) format(result *strings.Builder, value *types.Value) error {
+	switch spec.verb {
+	case 's':
+		s := value.ToString()
+		if spec.precision >= 0 && spec.precision < len(s) {
+			s = s[:spec.precision]
+		}
+		spec.pad(result, s, false)
+
+	case 'd':
+		n := value.ToInt()
+		s := strconv.FormatInt(n, 10)
+		if n >= 0 && spec.alwaysSign {
+			s = "+" + s
+		}
+		spec.pad(result, s, n < 0 || spec.alwaysSign)
+
+	case 'u':
+		spec.pad(result, strconv.FormatUint(uint64(value.ToInt()), 10), false)
+
+	case 'b':
+		spec.pad(result, strconv.FormatUint(uint64(value.ToInt()), 2), false)
+
+	case 'o':
+		spec.pad(result, strconv.FormatUint(uint64(value.ToInt()), 8), false)
+
+	case 'x':
+		spec.pad(result, strconv.FormatUint(uint64(value.ToInt()), 16), false)
+
+	case 'X':
+		spec.pad(result, strings.ToUpper(strconv.FormatUint(uint64(value.ToInt()), 16)), false)
+
+	case 'c':
+		// Width and padding do not apply to characters
+		result.WriteByte(byte(value.ToInt()))
+
+	case 'e', 'E', 'f', 'F', 'g', 'G', 'h', 'H':
+		if spec.shortest && spec.verb != 'g' && spec.verb != 'G' && spec.verb != 'h' && spec.verb != 'H' {
+			return valueError("Precision -1 is only supported for %%g, %%G, %%h and %%H")
+		}
+		f := value.ToFloat()
+		s := spec.formatFloat(math.Abs(f))
+		negative := math.Signbit(f) && !math.IsNaN(f)
+		if negative {
+			s = "-" + s
+		} else if spec.alwaysSign {
+			s = "+" + s
+		}
+		spec.pad(result, s, negative || spec.alwaysSign)
+
+	default:
+		return valueError("Unknown format specifier \"%c\"", spec.verb)
+	}
+	return nil
+}
+
+// formatFloat formats a non-negative float for the e, f and g families
+func (spec formatSpec) formatFloat(f float64) string {
+	if math.IsNaN(f) {
+		return "NaN"
+	}
+	if math.IsInf(f, 0) {
+		return "Inf"
+	}
+
+	precision := spec.precision
+	if precision < 0 {
+		precision = 6
+	}
+	precision = min(precision, maxFloatPrecision)
+
+	switch spec.verb {
+	case 'e', 'E':
+		s := strconv.FormatFloat(f, 'e', precision, 64)
+		// PHP writes exponents without leading zeros: 1.5e+3
+		mantissa, exponent, _ := strings.Cut(s, "e")
+		digits := strings.TrimLeft(exponent[1:], "0")
+		if digits == "" {
+			digits = "0"
+		}
+		s = mantissa + "e" + exponent[:1] + digits
+		if spec.verb == 'E' {
+			s = strings.ToUpper(s)
+		}
+		return s
+
+	case 'f', 'F':
+		return strconv.FormatFloat(f, 'f', precision, 64)
+
+	default:
+		if spec.precision == 0 {
+			precision = 1
+		}
+		exponent := byte('e')
+		if spec.verb == 'G' || spec.verb == 'H' {
+			exponent = 'E'
+		}
+		if spec.shortest {
+			// Precision -1 selects the shortest representation
+			return formatGeneral(f, -1, 17, exponent)
+		}
+		return formatGeneral(f, precision, precision, exponent)
+	}
+}
+
+// formatGeneral formats a non-negative float with the given significant
+// digits the way PHP's %g does: plain notation unless the decimal
+// exponent is below -4 or above limit, and exponents written as 1.0e+25.
+func formatGeneral(f float64, digits, limit int, exponent byte) string {
+	if f == 0 {
+		return "0"
+	}
+
+	// Significant digits without trailing zeros and the position of the
+	// decimal point relative to them (value = 0.DIGITS * 10^point)
+	mantissa, exp, _ := strings.Cut(strconv.FormatFloat(f, 'e', max(digits-1, -1), 64), "e")
+	significant := strings.TrimRight(strings.Replace(mantissa, ".", "", 1), "0")
+	e, _ := strconv.Atoi(exp)
+	point := e + 1
+
+	var s strings.Builder
+	switch {
+	case point < -3 || point > limit:
+		s.WriteByte(significant[0])
+		s.WriteByte('.')
+		if len(significant) == 1 {
+			s.WriteByte('0')
+		} else {
+			s.WriteString(significant[1:])
+		}
+		s.WriteByte(exponent)
+		if e < 0 {
+			s.WriteByte('-')
+			e = -e
+		} else {
+			s.WriteByte('+')
+		}
+		s.WriteString(strconv.Itoa(e))
+
+	case point <= 0:
+		s.WriteString("0.")
+		s.WriteString(strings.Repeat("0", -point))
+		s.WriteString(significant)
+
+	default:
+		if len(significant) <= point {
+			s.WriteString(significant)
+			s.WriteString(strings.Repeat("0", point-len(significant)))
+		} else {
+			s.WriteString(significant[:point])
+			s.WriteByte('.')
+			s.WriteString(significant[point:])
+		}
+	}
+	return s.String()
+}
+
+// pad appends s padded to the specification's width. Left-aligned values
+// are padded on the right with the padding character (zeros included);
+// when zero-padding a signed number, the sign stays in front.
+func (spec formatSpec) pad(result *strings.Builder, s string, signed bool) {
+	n := spec.width - len(s)
+	if n <= 0 {
+		result.WriteString(s)
+		return
+	}
+
+	padding := strings.Repeat(string(spec.padding), n)
+	switch {
+	case spec.leftAlign:
+		result.WriteString(s)
+		result.WriteString(padding)
+	case signed && spec.padding == '0':
+		result.WriteByte(s[0])
+		result.WriteString(padding)
+		result.WriteString(s[1:])
+	default:
+		result.WriteString(padding)
+		result.WriteString(s)
+	}
+}
@@ -0,0 +1,121 @@
+package string
+
+import (
+	"math"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		format   string
+		args     []*types.Value
+		expected string
+	}{
+		{"%s", []*types.Value{types.NewString("abc")}, "abc"},
+		{"[%5s]", []*types.Value{types.NewString("abc")}, "[  abc]"},
+		{"[%-5s]", []*types.Value{types.NewString("abc")}, "[abc  ]"},
+		{"[%05s]", []*types.Value{types.NewString("abc")}, "[00abc]"},
+		{"[%'*5s]", []*types.Value{types.NewString("abc")}, "[**abc]"},
+		{"[%-'x6s]", []*types.Value{types.NewString("abc")}, "[abcxxx]"},
+		{"[%.2s]", []*types.Value{types.NewString("abc")}, "[ab]"},
+		{"[%5.1s]", []*types.Value{types.NewString("abc")}, "[    a]"},
+		{"%d", []*types.Value{types.NewString("42abc")}, "42"},
+		{"%+d %+d", []*types.Value{types.NewInt(5), types.NewInt(-5)}, "+5 -5"},
+		{"[%05d]", []*types.Value{types.NewInt(-42)}, "[-0042]"},
+		{"[%+05d]", []*types.Value{types.NewInt(42)}, "[+0042]"},
+		{"[%-05d]", []*types.Value{types.NewInt(42)}, "[42000]"},
+		{"[%5d]", []*types.Value{types.NewInt(-42)}, "[  -42]"},
+		{"%u", []*types.Value{types.NewInt(-1)}, "18446744073709551615"},
+		{"%b %o %x %X", []*types.Value{types.NewInt(5), types.NewInt(8), types.NewInt(255), types.NewInt(255)}, "101 10 ff FF"},
+		{"%08b", []*types.Value{types.NewInt(5)}, "00000101"},
+		{"%x", []*types.Value{types.NewInt(-1)}, "ffffffffffffffff"},
+		{"%c%c", []*types.Value{types.NewInt(80), types.NewInt(72)}, "PH"},
+		{"[%5c]", []*types.Value{types.NewInt(65)}, "[A]"},
+		{"%f", []*types.Value{types.NewFloat(3.14159)}, "3.141590"},
+		{"%.2f", []*types.Value{types.NewFloat(3.14159)}, "3.14"},
+		{"%.0f", []*types.Value{types.NewFloat(2.5)}, "2"},
+		{"[%08.3f]", []*types.Value{types.NewFloat(-3.14159)}, "[-003.142]"},
+		{"%+.1F", []*types.Value{types.NewFloat(2)}, "+2.0"},
+		{"%e", []*types.Value{types.NewFloat(12.345)}, "1.234500e+1"},
+		{"%.2E", []*types.Value{types.NewFloat(0.000123)}, "1.23E-4"},
+		{"%.0e", []*types.Value{types.NewFloat(12)}, "1e+1"},
+		{"%g", []*types.Value{types.NewFloat(0.0001)}, "0.0001"},
+		{"%g", []*types.Value{types.NewFloat(0.00001)}, "1.0e-5"},
+		{"%g", []*types.Value{types.NewFloat(100000)}, "100000"},
+		{"%g", []*types.Value{types.NewFloat(1000000)}, "1.0e+6"},
+		{"%G", []*types.Value{types.NewFloat(1.5e25)}, "1.5E+25"},
+		{"%.3g", []*types.Value{types.NewFloat(3.14159)}, "3.14"},
+		{"%g", []*types.Value{types.NewFloat(0)}, "0"},
+		{"%h", []*types.Value{types.NewFloat(-1234.5)}, "-1234.5"},
+		{"%.*g", []*types.Value{types.NewInt(-1), types.NewFloat(0.1)}, "0.1"},
+		{"%f %f", []*types.Value{types.NewFloat(math.Inf(-1)), types.NewFloat(math.NaN())}, "-Inf NaN"},
+		{"%2$s %1$s %2$s", []*types.Value{types.NewString("a"), types.NewString("b")}, "b a b"},
+		{"%1$s %s %s", []*types.Value{types.NewString("a"), types.NewString("b")}, "a a b"},
+		{"%1$'#10.3f", []*types.Value{types.NewFloat(1.5)}, "#####1.500"},
+		{"[%*d]", []*types.Value{types.NewInt(4), types.NewInt(7)}, "[   7]"},
+		{"[%-*.*f]", []*types.Value{types.NewInt(7), types.NewInt(1), types.NewFloat(2.25)}, "[2.2    ]"},
+		{"%ld", []*types.Value{types.NewInt(9)}, "9"},
+		{"100%%", nil, "100%"},
+		{"%5%", nil, "%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			result, err := Format(tt.format, tt.args)
+			if err != nil {
+				t.Fatalf("Format(%q) error: %v", tt.format, err)
+			}
+			if result != tt.expected {
+				t.Errorf("Format(%q) = %q, expected %q", tt.format, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	tests := []struct {
+		format  string
+		args    []*types.Value
+		class   string
+		message string
+	}{
+		{"%s %s", []*types.Value{types.NewString("a")}, "ArgumentCountError", "3 arguments are required, 2 given"},
+		{"%3$s", nil, "ArgumentCountError", "4 arguments are required, 1 given"},
+		{"%0$s", nil, "ValueError", "Argument number specifier must be greater than zero and less than 2147483647"},
+		{"%y", []*types.Value{types.NewInt(1)}, "ValueError", "Unknown format specifier \"y\""},
+		{"abc %", nil, "ValueError", "Missing format specifier at end of string"},
+		{"%'", nil, "ValueError", "Missing padding character"},
+		{"%.*f", []*types.Value{types.NewInt(-1), types.NewFloat(1)}, "ValueError", "Precision -1 is only supported for %g, %G, %h and %H"},
+		{"%*d", []*types.Value{types.NewString("x"), types.NewInt(1)}, "ValueError", "Width must be an integer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			_, err := Format(tt.format, tt.args)
+			class := ""
+			switch e := err.(type) {
+			case *FormatError:
+				class = e.Class
+			case *MissingArgumentsError:
+				class = "ArgumentCountError"
+			default:
+				t.Fatalf("Format(%q) expected an error, got %v", tt.format, err)
+			}
+			if class != tt.class || err.Error() != tt.message {
+				t.Errorf("Format(%q) error = %s: %q, expected %s: %q", tt.format, class, err.Error(), tt.class, tt.message)
+			}
+		})
+	}
+}
+
+func TestVsprintf(t *testing.T) {
+	args := types.NewArray(types.NewArrayFromSlice([]*types.Value{types.NewString("x"), types.NewInt(3)}))
+	if result := Vsprintf(types.NewString("%s=%03d"), args); result.ToString() != "x=003" {
+		t.Errorf("Expected 'x=003', got %q", result.ToString())
+	}
+	if result := Sprintf(types.NewString("%s %s"), types.NewString("a")); result.Type() != types.TypeBool {
+		t.Errorf("Expected false for missing arguments, got %v", result)
+	}
+}
@@ -46,20 +46,17 @@ func Substr(str *types.Value, offset *types.Value, length ...*types.Value) *type
 
 	// Calculate end position
 	end := strLen
-	if len(length) > 0 && length[0] != nil {
-		lengthInt := int(length[0].ToInt())
+	if len(length) > 0 && length[0] != nil && !length[0].IsNull() {
+		lengthInt := length[0].ToInt()
 
 		if lengthInt < 0 {
 			// Negative length means "all except last N"
-			end = strLen + lengthInt
-			if end < start {
+			if lengthInt < int64(start-strLen) {
 				return types.NewString("")
 			}
-		} else {
-			end = start + lengthInt
-			if end > strLen {
-				end = strLen
-			}
+			end = strLen + int(lengthInt)
+		} else if lengthInt < int64(strLen-start) {
+			end = start + int(lengthInt)
 		}
 	}
 
@@ -133,8 +130,8 @@ func Strrpos(haystack *types.Value, needle *types.Value, offset ...*types.Value)
 // Stripos finds position of first occurrence (case-insensitive)
 // stripos(string $haystack, string $needle, int $offset = 0): int|false
 func Stripos(haystack *types.Value, needle *types.Value, offset ...*types.Value) *types.Value {
-	h := strings.ToLower(haystack.ToString())
-	n := strings.ToLower(needle.ToString())
+	h := asciiLower(haystack.ToString())
+	n := asciiLower(needle.ToString())
 
 	if n == "" {
 		return types.NewBool(false)
@@ -159,8 +156,8 @@ func Stripos(haystack *types.Value, needle *types.Value, offset ...*types.Value)
 // Strripos finds position of last occurrence (case-insensitive)
 // strripos(string $haystack, string $needle, int $offset = 0): int|false
 func Strripos(haystack *types.Value, needle *types.Value, offset ...*types.Value) *types.Value {
-	h := strings.ToLower(haystack.ToString())
-	n := strings.ToLower(needle.ToString())
+	h := asciiLower(haystack.ToString())
+	n := asciiLower(needle.ToString())
 
 	if n == "" {
 		return types.NewBool(false)
@@ -211,8 +208,8 @@ func StrIreplace(search *types.Value, replace *types.Value, subject *types.Value
 
 	// Case-insensitive replacement
 	// We'll use a simple approach: find and replace manually
-	lowerSubj := strings.ToLower(subj)
-	lowerSearch := strings.ToLower(s)
+	lowerSubj := asciiLower(subj)
+	lowerSearch := asciiLower(s)
 
 	result := ""
 	lastIdx := 0
@@ -241,14 +238,14 @@ func StrIreplace(search *types.Value, replace *types.Value, subject *types.Value
 // strtolower(string $string): string
 func Strtolower(str *types.Value) *types.Value {
 	s := str.ToString()
-	return types.NewString(strings.ToLower(s))
+	return types.NewString(asciiLower(s))
 }
 
 // Strtoupper converts string to uppercase
 // strtoupper(string $string): string
 func Strtoupper(str *types.Value) *types.Value {
 	s := str.ToString()
-	return types.NewString(strings.ToUpper(s))
+	return types.NewString(asciiUpper(s))
 }
 
 // Ucfirst makes the first character uppercase
@@ -259,7 +256,7 @@ func Ucfirst(str *types.Value) *types.Value {
 		return types.NewString("")
 	}
 
-	return types.NewString(strings.ToUpper(s[:1]) + s[1:])
+	return types.NewString(asciiUpper(s[:1]) + s[1:])
 }
 
 // Lcfirst makes the first character lowercase
@@ -270,14 +267,53 @@ func Lcfirst(str *types.Value) *types.Value {
 		return types.NewString("")
 	}
 
-	return types.NewString(strings.ToLower(s[:1]) + s[1:])
+	return types.NewString(asciiLower(s[:1]) + s[1:])
 }
 
-// Ucwords makes the first character of each word uppercase
-// ucwords(string $string): string
-func Ucwords(str *types.Value) *types.Value {
-	s := str.ToString()
-	return types.NewString(strings.Title(s))
+// Ucwords makes the first character of each word uppercase, words being
+// separated by any of the delimiters
+// ucwords(string $string, string $separators = " \t\r\n\f\v"): string
+func Ucwords(str *types.Value, delimiters ...*types.Value) *types.Value {
+	separators := " \t\r\n\f\v"
+	if len(delimiters) > 0 {
+		separators = delimiters[0].ToString()
+	}
+	b := []byte(str.ToString())
+	for i := range b {
+		if i == 0 || strings.IndexByte(separators, b[i-1]) >= 0 {
+			b[i] = upperByte(b[i])
+		}
+	}
+	return types.NewString(string(b))
+}
+
+// asciiLower lowercases the ASCII letters of a string, leaving other bytes
+// alone like PHP 8's locale-insensitive case functions, so that the result
+// has the same length and invalid UTF-8 survives
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, ch := range b {
+		if ch >= 'A' && ch <= 'Z' {
+			b[i] = ch + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// asciiUpper uppercases the ASCII letters of a string, like asciiLower
+func asciiUpper(s string) string {
+	b := []byte(s)
+	for i, ch := range b {
+		b[i] = upperByte(ch)
+	}
+	return string(b)
+}
+
+func upperByte(ch byte) byte {
+	if ch >= 'a' && ch <= 'z' {
+		return ch - ('a' - 'A')
+	}
+	return ch
 }
 
 // ============================================================================
@@ -498,17 +534,16 @@ func StrPad(str *types.Value, length *types.Value, padString *types.Value, padTy
 	return types.NewString(s + padding)
 }
 
-// StrRev reverses a string
+// StrRev reverses the bytes of a string
 // strrev(string $string): string
 func StrRev(str *types.Value) *types.Value {
-	s := str.ToString()
-	runes := []rune(s)
+	b := []byte(str.ToString())
 
-	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
-		runes[i], runes[j] = runes[j], runes[i]
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
 	}
 
-	return types.NewString(string(runes))
+	return types.NewString(string(b))
 }
 
 // Strstr finds the first occurrence of a string (returns substring from match)
@@ -543,98 +578,60 @@ func Strchr(haystack *types.Value, needle *types.Value, beforeNeedle ...*types.V
 // String Formatting Functions
 // ============================================================================
 
-// Sprintf returns a formatted string
+// Sprintf returns a formatted string (see Format); it returns false
+// when the format is invalid or arguments are missing
 // sprintf(string $format, mixed ...$values): string
 func Sprintf(format *types.Value, values ...*types.Value) *types.Value {
 	if format == nil {
 		return types.NewString("")
 	}
 
-	f := format.ToString()
-	result := formatString(f, values)
+	result, err := Format(format.ToString(), values)
+	if err != nil {
+		return types.NewBool(false)
+	}
 	return types.NewString(result)
 }
 
-// Printf outputs a formatted string
+// Vsprintf returns a string formatted with the values of an array
+// vsprintf(string $format, array $values): string
+func Vsprintf(format *types.Value, values *types.Value) *types.Value {
+	return Sprintf(format, FormatArgs(values)...)
+}
+
+// FormatArgs returns the values of a vsprintf() argument array in order
+func FormatArgs(values *types.Value) []*types.Value {
+	var result []*types.Value
+	if values == nil || !values.IsArray() {
+		return result
+	}
+	values.ToArray().Each(func(key, value *types.Value) bool {
+		result = append(result, value)
+		return true
+	})
+	return result
+}
+
+// Printf formats a string like Sprintf and returns its length; the
+// caller writes the output
 // printf(string $format, mixed ...$values): int
 func Printf(format *types.Value, values ...*types.Value) *types.Value {
 	result := Sprintf(format, values...)
 	output := result.ToString()
-	// In a real implementation, this would write to stdout
-	// For now, we just return the length
 	return types.NewInt(int64(len(output)))
 }
 
-// formatString implements basic sprintf-style formatting
-func formatString(format string, values []*types.Value) string {
-	var result strings.Builder
-	valueIdx := 0
-
-	for i := 0; i < len(format); i++ {
-		if format[i] != '%' {
-			result.WriteByte(format[i])
-			continue
-		}
-
-		// Handle %%
-		if i+1 < len(format) && format[i+1] == '%' {
-			result.WriteByte('%')
-			i++
-			continue
-		}
-
-		// No more values
-		if valueIdx >= len(values) {
-			result.WriteByte('%')
-			continue
-		}
-
-		// Parse format specifier
-		i++
-		if i >= len(format) {
-			break
-		}
-
-		// Skip padding/width for now (simplified implementation)
-		for i < len(format) && (format[i] == '-' || format[i] == '+' || format[i] == ' ' || format[i] == '0' || (format[i] >= '0' && format[i] <= '9') || format[i] == '.') {
-			i++
-		}
-
-		if i >= len(format) {
-			break
-		}
-
-		// Handle format type
-		value := values[valueIdx]
-		valueIdx++
-
-		switch format[i] {
-		case 's': // String
-			result.WriteString(value.ToString())
-		case 'd', 'i': // Integer
-			result.WriteString(value.ToString())
-		case 'f', 'F': // Float
-			result.WriteString(value.ToString())
-		case 'x': // Hex lowercase
-			result.WriteString(value.ToString())
-		case 'X': // Hex uppercase
-			result.WriteString(strings.ToUpper(value.ToString()))
-		case 'c': // Character
-			if value.Type() == types.TypeInt {
-				result.WriteByte(byte(value.ToInt()))
-			} else {
-				s := value.ToString()
-				if len(s) > 0 {
-					result.WriteByte(s[0])
-				}
-			}
-		default:
-			result.WriteByte('%')
-			result.WriteByte(format[i])
-		}
+// Sscanf parses a string according to a format (see Scan) and returns
+// the values as an array, or null when the string ended before the first
+// conversion
+// sscanf(string $string, string $format): array|null
+func Sscanf(str *types.Value, format *types.Value) *types.Value {
+	values, count, err := Scan(str.ToString(), format.ToString())
+	if err != nil || count == -1 {
+		return types.NewNull()
 	}
 
-	return result.String()
+	return types.NewArray(types.NewArrayFromSlice(values))
 }
 
 // ============================================================================
@@ -659,8 +656,8 @@ func Strcmp(str1 *types.Value, str2 *types.Value) *types.Value {
 // Strcasecmp performs case-insensitive string comparison
 // strcasecmp(string $string1, string $string2): int
 func Strcasecmp(str1 *types.Value, str2 *types.Value) *types.Value {
-	s1 := strings.ToLower(str1.ToString())
-	s2 := strings.ToLower(str2.ToString())
+	s1 := asciiLower(str1.ToString())
+	s2 := asciiLower(str2.ToString())
 
 	if s1 == s2 {
 		return types.NewInt(0)
@@ -701,8 +698,8 @@ func Strncmp(str1 *types.Value, str2 *types.Value, length *types.Value) *types.V
 // Strncasecmp performs case-insensitive string comparison of first n characters
 // strncasecmp(string $string1, string $string2, int $length): int
 func Strncasecmp(str1 *types.Value, str2 *types.Value, length *types.Value) *types.Value {
-	s1 := strings.ToLower(str1.ToString())
-	s2 := strings.ToLower(str2.ToString())
+	s1 := asciiLower(str1.ToString())
+	s2 := asciiLower(str2.ToString())
 	n := int(length.ToInt())
 
 	if n <= 0 {
@@ -728,8 +725,8 @@ func Strncasecmp(str1 *types.Value, str2 *types.Value, length *types.Value) *typ
 // Stristr finds the first occurrence of a string (case-insensitive)
 // stristr(string $haystack, mixed $needle, bool $before_needle = false): string|false
 func Stristr(haystack *types.Value, needle *types.Value, beforeNeedle ...*types.Value) *types.Value {
-	h := strings.ToLower(haystack.ToString())
-	n := strings.ToLower(needle.ToString())
+	h := asciiLower(haystack.ToString())
+	n := asciiLower(needle.ToString())
 	hOrig := haystack.ToString()
 
 	index := strings.Index(h, n)
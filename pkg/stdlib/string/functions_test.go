@@ -1,6 +1,7 @@
 package string
 
 import (
+	"math"
 	"strings"
 	"testing"
 
@@ -20,6 +21,25 @@ func TestStrlen(t *testing.T) {
 	}
 }
 
+func TestStrlenSubstr_BinarySafe(t *testing.T) {
+	str := types.NewString("a\x00\xff\xfeb")
+	if n := Strlen(str).ToInt(); n != 5 {
+		t.Errorf("Expected 5 bytes, got %d", n)
+	}
+	if result := Substr(str, types.NewInt(1), types.NewInt(3)).ToString(); result != "\x00\xff\xfe" {
+		t.Errorf("Expected the middle bytes, got %q", result)
+	}
+	if result := Substr(str, types.NewInt(-2), types.NewNull()).ToString(); result != "\xfeb" {
+		t.Errorf("Expected a null length to mean the rest, got %q", result)
+	}
+	if result := Substr(str, types.NewInt(1), types.NewInt(math.MaxInt64)).ToString(); result != "\x00\xff\xfeb" {
+		t.Errorf("Expected the rest of the string, got %q", result)
+	}
+	if result := StrRev(str).ToString(); result != "b\xfe\xff\x00a" {
+		t.Errorf("Expected the bytes reversed, got %q", result)
+	}
+}
+
 func TestStrlenEmpty(t *testing.T) {
 	str := types.NewString("")
 	result := Strlen(str)
@@ -149,6 +169,24 @@ func TestStrtoupper(t *testing.T) {
 	}
 }
 
+func TestCaseConversion_BinarySafe(t *testing.T) {
+	// Only ASCII letters change: other bytes, NUL and invalid UTF-8
+	// included, are kept as they are
+	str := types.NewString("\xc9t\xe9 A\x00\xff")
+	if result := Strtolower(str).ToString(); result != "\xc9t\xe9 a\x00\xff" {
+		t.Errorf("Expected ASCII-only lowercasing, got %q", result)
+	}
+	if result := Strtoupper(str).ToString(); result != "\xc9T\xe9 A\x00\xff" {
+		t.Errorf("Expected ASCII-only uppercasing, got %q", result)
+	}
+	if result := Stripos(types.NewString("\xc9\xc9ab"), types.NewString("B")); result.ToInt() != 3 {
+		t.Errorf("Expected byte offset 3, got %v", result)
+	}
+	if result := Ucwords(types.NewString("hello_world-foo bar"), types.NewString("_-")).ToString(); result != "Hello_World-Foo bar" {
+		t.Errorf("Expected 'Hello_World-Foo bar', got %q", result)
+	}
+}
+
 func TestUcfirst(t *testing.T) {
 	str := types.NewString("hello world")
 	result := Ucfirst(str)
@@ -0,0 +1,367 @@
+package string
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// sscanf Scanner
+// ============================================================================
+
+// scanItem is one element of a parsed sscanf() format
+type scanItem struct {
+	literal  byte // character to match, 0 for conversions and whitespace
+	space    bool // any run of whitespace, including none
+	verb     byte
+	width    int
+	suppress bool       // %*d: read but do not assign
+	index    int        // position of the assigned value
+	set      *[256]bool // %[...] character set
+}
+
+// parseScanFormat parses an sscanf() format into items and returns the
+// number of values its conversions assign
+func parseScanFormat(format string) ([]scanItem, int, error) {
+	var items []scanItem
+	next, sequential, positional := 0, false, false
+	assigned := map[int]bool{}
+
+	for i := 0; i < len(format); {
+		c := format[i]
+		if isScanSpace(c) {
+			for i < len(format) && isScanSpace(format[i]) {
+				i++
+			}
+			items = append(items, scanItem{space: true})
+			continue
+		}
+		i++
+		if c != '%' {
+			items = append(items, scanItem{literal: c})
+			continue
+		}
+		if i < len(format) && format[i] == '%' {
+			items = append(items, scanItem{literal: '%'})
+			i++
+			continue
+		}
+
+		item := scanItem{index: -1}
+		if i < len(format) && format[i] == '*' {
+			item.suppress = true
+			i++
+		} else if j, n, ok := scanFormatNumber(format, i); ok && j < len(format) && format[j] == '$' {
+			if n == 0 {
+				return nil, 0, valueError("\"%%n$\" argument index out of range")
+			}
+			item.index, i = n-1, j+1
+		}
+		if j, n, ok := scanFormatNumber(format, i); ok {
+			item.width, i = n, j
+		}
+		// Size modifiers are accepted and ignored
+		for i < len(format) && (format[i] == 'l' || format[i] == 'L' || format[i] == 'h') {
+			i++
+		}
+		if i >= len(format) {
+			return nil, 0, valueError("Bad scan conversion character \"\"")
+		}
+
+		item.verb = format[i]
+		i++
+		switch item.verb {
+		case 'd', 'i', 'o', 'x', 'X', 'u', 'f', 'e', 'E', 'g', 's', 'c', 'n':
+		case '[':
+			set, end, ok := parseScanSet(format, i)
+			if !ok {
+				return nil, 0, valueError("Unmatched [ in format string")
+			}
+			item.set, i = set, end
+		default:
+			return nil, 0, valueError("Bad scan conversion character \"%c\"", item.verb)
+		}
+
+		if !item.suppress {
+			if item.index < 0 {
+				item.index = next
+				next++
+				sequential = true
+			} else {
+				positional = true
+				if assigned[item.index] {
+					return nil, 0, valueError("Variable is assigned by multiple \"%%n$\" conversion specifiers")
+				}
+				next = max(next, item.index+1)
+			}
+			assigned[item.index] = true
+		}
+		if sequential && positional {
+			return nil, 0, valueError("cannot mix \"%%\" and \"%%n$\" conversion specifiers")
+		}
+		items = append(items, item)
+	}
+
+	if len(assigned) != next {
+		return nil, 0, valueError("Variable is not assigned by any conversion specifiers")
+	}
+	return items, next, nil
+}
+
+// parseScanSet parses the character set of %[...] starting after the [.
+// A leading ^ negates the set and a ] right after [ or [^ is a member;
+// a-z denotes a range.
+func parseScanSet(format string, i int) (*[256]bool, int, bool) {
+	var set [256]bool
+	negate := i < len(format) && format[i] == '^'
+	if negate {
+		i++
+	}
+	start := i
+	for ; i < len(format); i++ {
+		c := format[i]
+		if c == ']' && i > start {
+			if negate {
+				for n := range set {
+					set[n] = !set[n]
+				}
+			}
+			return &set, i + 1, true
+		}
+		if i+2 < len(format) && format[i+1] == '-' && format[i+2] != ']' {
+			lo, hi := c, format[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			for n := int(lo); n <= int(hi); n++ {
+				set[n] = true
+			}
+			i += 2
+			continue
+		}
+		set[c] = true
+	}
+	return nil, i, false
+}
+
+func isScanSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+// Scan parses str according to an sscanf() format. Whitespace in the
+// format matches any run of whitespace, other characters match
+// themselves, and the d, i, o, x, X, u, f, e, E, g, s, c, [set] and n
+// conversions read values; a conversion may have a width, * to skip the
+// value, or n$ to choose its position. It returns one value per assigned
+// conversion (null where the input ran out or did not match) and the
+// number of values assigned, which is -1 when the input ended before the
+// first conversion.
+func Scan(str, format string) ([]*types.Value, int, error) {
+	items, total, err := parseScanFormat(format)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	values := make([]*types.Value, total)
+	for n := range values {
+		values[n] = types.NewNull()
+	}
+
+	pos, count, underflow := 0, 0, false
+scan:
+	for _, item := range items {
+		switch {
+		case item.space:
+			for pos < len(str) && isScanSpace(str[pos]) {
+				pos++
+			}
+			continue
+		case item.literal != 0:
+			if pos >= len(str) {
+				underflow = true
+				break scan
+			}
+			if str[pos] != item.literal {
+				break scan
+			}
+			pos++
+			continue
+		case item.verb == 'n':
+			if !item.suppress {
+				values[item.index] = types.NewInt(int64(pos))
+			}
+			continue
+		}
+
+		if item.verb != 'c' && item.verb != '[' {
+			for pos < len(str) && isScanSpace(str[pos]) {
+				pos++
+			}
+		}
+		if pos >= len(str) {
+			underflow = true
+			break
+		}
+
+		limit := len(str)
+		if item.width > 0 {
+			limit = min(limit, pos+item.width)
+		} else if item.verb == 'c' {
+			limit = pos + 1
+		}
+
+		value, end := scanValue(str[:limit], pos, item)
+		if value == nil {
+			break
+		}
+		pos = end
+		if !item.suppress {
+			values[item.index] = value
+			count++
+		}
+	}
+
+	if underflow && count == 0 {
+		return values, -1, nil
+	}
+	return values, count, nil
+}
+
+// scanValue reads the value of one conversion from str[pos:]. It returns
+// nil when the input does not match.
+func scanValue(str string, pos int, item scanItem) (*types.Value, int) {
+	start := pos
+	switch item.verb {
+	case 's':
+		for pos < len(str) && !isScanSpace(str[pos]) {
+			pos++
+		}
+		return types.NewString(str[start:pos]), pos
+
+	case 'c':
+		return types.NewString(str[start:]), len(str)
+
+	case '[':
+		for pos < len(str) && item.set[str[pos]] {
+			pos++
+		}
+		if pos == start {
+			return nil, start
+		}
+		return types.NewString(str[start:pos]), pos
+
+	case 'f', 'e', 'E', 'g':
+		end := scanFloat(str, pos)
+		if end == start {
+			return nil, start
+		}
+		f, _ := strconv.ParseFloat(str[start:end], 64)
+		return types.NewFloat(f), end
+
+	default:
+		return scanInteger(str, pos, item.verb)
+	}
+}
+
+// scanFloat returns the end of the float literal at str[pos:]
+func scanFloat(str string, pos int) int {
+	start := pos
+	if pos < len(str) && (str[pos] == '+' || str[pos] == '-') {
+		pos++
+	}
+	digits := 0
+	for pos < len(str) && str[pos] >= '0' && str[pos] <= '9' {
+		pos++
+		digits++
+	}
+	if pos < len(str) && str[pos] == '.' {
+		pos++
+		for pos < len(str) && str[pos] >= '0' && str[pos] <= '9' {
+			pos++
+			digits++
+		}
+	}
+	if digits == 0 {
+		return start
+	}
+	if pos < len(str) && (str[pos] == 'e' || str[pos] == 'E') {
+		exp := pos + 1
+		if exp < len(str) && (str[exp] == '+' || str[exp] == '-') {
+			exp++
+		}
+		if exp < len(str) && str[exp] >= '0' && str[exp] <= '9' {
+			for exp < len(str) && str[exp] >= '0' && str[exp] <= '9' {
+				exp++
+			}
+			pos = exp
+		}
+	}
+	return pos
+}
+
+// scanInteger reads an integer in the base of the conversion; %i detects
+// the base from a 0x or 0 prefix. Integers that do not fit are returned
+// as strings.
+func scanInteger(str string, pos int, verb byte) (*types.Value, int) {
+	start := pos
+	negative := false
+	if pos < len(str) && (str[pos] == '+' || str[pos] == '-') {
+		negative = str[pos] == '-'
+		pos++
+	}
+
+	base := 10
+	switch verb {
+	case 'o':
+		base = 8
+	case 'x', 'X':
+		base = 16
+	case 'i':
+		base = 0
+	}
+	if (base == 16 || base == 0) && pos+1 < len(str) && str[pos] == '0' && (str[pos+1] == 'x' || str[pos+1] == 'X') &&
+		pos+2 < len(str) && digitValue(str[pos+2]) < 16 {
+		base, pos = 16, pos+2
+	} else if base == 0 && pos < len(str) && str[pos] == '0' {
+		base = 8
+	} else if base == 0 {
+		base = 10
+	}
+
+	digits := pos
+	for pos < len(str) && digitValue(str[pos]) < base {
+		pos++
+	}
+	if pos == digits {
+		return nil, start
+	}
+
+	text := str[digits:pos]
+	if negative {
+		text = "-" + text
+	}
+	if verb == 'u' && negative {
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return types.NewString(strconv.FormatUint(uint64(n), 10)), pos
+		}
+	}
+	if n, err := strconv.ParseInt(text, base, 64); err == nil {
+		return types.NewInt(n), pos
+	}
+	return types.NewString(strings.TrimPrefix(str[start:pos], "+")), pos
+}
+
+// digitValue returns the value of a digit in bases up to 16, or 16
+func digitValue(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10
+	}
+	return 16
+}
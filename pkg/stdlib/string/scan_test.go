@@ -0,0 +1,87 @@
+package string
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		str      string
+		format   string
+		expected []interface{}
+		count    int
+	}{
+		{"age: 25 name: Bob", "age: %d name: %s", []interface{}{int64(25), "Bob"}, 2},
+		{"12 apples", "%d %s", []interface{}{int64(12), "apples"}, 2},
+		{"2024-01-15", "%4d-%2d-%2d", []interface{}{int64(2024), int64(1), int64(15)}, 3},
+		{"  -42", "%d", []interface{}{int64(-42)}, 1},
+		{"ff 0x1F 017 10", "%x %i %i %o", []interface{}{int64(255), int64(31), int64(15), int64(8)}, 4},
+		{"3.5e2 -0.25", "%f %e", []interface{}{350.0, -0.25}, 2},
+		{"abc", "%c%c", []interface{}{"a", "b"}, 2},
+		{"hello world", "%5c%n", []interface{}{"hello", int64(5)}, 1},
+		{"id=abc123;", "id=%[a-z]%[0-9]", []interface{}{"abc", "123"}, 2},
+		{"key: value", "%[^:]: %s", []interface{}{"key", "value"}, 2},
+		{"skip 7", "%*s %d", []interface{}{int64(7)}, 1},
+		{"a b", "%2$s %1$s", []interface{}{"b", "a"}, 2},
+		{"12 x", "%d %d", []interface{}{int64(12), nil}, 1},
+		{"12", "%d %d", []interface{}{int64(12), nil}, 1},
+		{"x12", "y%d", []interface{}{nil}, 0},
+		{"", "%d", []interface{}{nil}, -1},
+		{"99999999999999999999", "%d", []interface{}{"99999999999999999999"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			values, count, err := Scan(tt.str, tt.format)
+			if err != nil {
+				t.Fatalf("Scan(%q, %q) error: %v", tt.str, tt.format, err)
+			}
+			if count != tt.count {
+				t.Errorf("Scan(%q, %q) count = %d, expected %d", tt.str, tt.format, count, tt.count)
+			}
+			if len(values) != len(tt.expected) {
+				t.Fatalf("Scan(%q, %q) returned %d values, expected %d", tt.str, tt.format, len(values), len(tt.expected))
+			}
+			for i, expected := range tt.expected {
+				var want *types.Value
+				switch e := expected.(type) {
+				case nil:
+					want = types.NewNull()
+				case int64:
+					want = types.NewInt(e)
+				case float64:
+					want = types.NewFloat(e)
+				case string:
+					want = types.NewString(e)
+				}
+				if values[i].Type() != want.Type() || values[i].ToString() != want.ToString() {
+					t.Errorf("value[%d] = %v (%s), expected %v (%s)", i, values[i], values[i].TypeName(), want, want.TypeName())
+				}
+			}
+		})
+	}
+}
+
+func TestScanFormatErrors(t *testing.T) {
+	formats := []string{"%y", "%[abc", "%d %1$d", "%1$d %1$d", "%2$d", "%"}
+
+	for _, format := range formats {
+		if _, _, err := Scan("1 2", format); err == nil {
+			t.Errorf("Scan(%q) expected an error", format)
+		} else if e, ok := err.(*FormatError); !ok || e.Class != "ValueError" {
+			t.Errorf("Scan(%q) expected a ValueError, got %v", format, err)
+		}
+	}
+}
+
+func TestSscanf(t *testing.T) {
+	result := Sscanf(types.NewString("x=1"), types.NewString("x=%d"))
+	if !result.IsArray() || result.ToArray().Len() != 1 {
+		t.Fatalf("Expected an array of 1 value, got %v", result)
+	}
+	if result := Sscanf(types.NewString(""), types.NewString("%d")); !result.IsNull() {
+		t.Errorf("Expected null for input that ended before the first conversion, got %v", result)
+	}
+}
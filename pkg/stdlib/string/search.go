@@ -0,0 +1,169 @@
+package string
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Substring Tests and Counting
+// ============================================================================
+
+// Error is an error thrown by a string function, such as the ValueError of
+// substr_count() for an empty needle. Class names the PHP exception.
+type Error struct {
+	Class   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// StrContains determines if a string contains a substring; every string
+// contains the empty string
+// str_contains(string $haystack, string $needle): bool
+func StrContains(haystack, needle *types.Value) *types.Value {
+	return types.NewBool(strings.Contains(haystack.ToString(), needle.ToString()))
+}
+
+// StrStartsWith checks if a string starts with a substring
+// str_starts_with(string $haystack, string $needle): bool
+func StrStartsWith(haystack, needle *types.Value) *types.Value {
+	return types.NewBool(strings.HasPrefix(haystack.ToString(), needle.ToString()))
+}
+
+// StrEndsWith checks if a string ends with a substring
+// str_ends_with(string $haystack, string $needle): bool
+func StrEndsWith(haystack, needle *types.Value) *types.Value {
+	return types.NewBool(strings.HasSuffix(haystack.ToString(), needle.ToString()))
+}
+
+// SubstrCount counts the non-overlapping occurrences of a substring,
+// optionally within the part of the string selected by offset and length
+// (negative values count from the end, as for substr()). Offsets and
+// lengths outside the string are a ValueError.
+// substr_count(string $haystack, string $needle, int $offset = 0, ?int $length = null): int
+func SubstrCount(haystack, needle *types.Value, args ...*types.Value) (*types.Value, error) {
+	h, n := haystack.ToString(), needle.ToString()
+	if n == "" {
+		return nil, &Error{Class: "ValueError", Message: "substr_count(): Argument #2 ($needle) cannot be empty"}
+	}
+
+	offset := int64(0)
+	if len(args) > 0 && args[0] != nil {
+		offset = args[0].ToInt()
+	}
+	if offset < 0 {
+		offset += int64(len(h))
+	}
+	if offset < 0 || offset > int64(len(h)) {
+		return nil, &Error{Class: "ValueError", Message: "substr_count(): Argument #3 ($offset) must be contained in argument #1 ($haystack)"}
+	}
+	h = h[offset:]
+
+	if len(args) > 1 && args[1] != nil && !args[1].IsNull() {
+		length := args[1].ToInt()
+		if length < 0 {
+			length += int64(len(h))
+		}
+		if length < 0 || length > int64(len(h)) {
+			return nil, &Error{Class: "ValueError", Message: "substr_count(): Argument #4 ($length) must be contained in argument #1 ($haystack)"}
+		}
+		h = h[:length]
+	}
+
+	return types.NewInt(int64(strings.Count(h, n))), nil
+}
+
+// ============================================================================
+// Character Translation
+// ============================================================================
+
+// Strtr translates characters or replaces substrings. With three
+// arguments each byte of from is replaced by the byte of to at the same
+// position (extra bytes of the longer one are ignored). With an array of
+// replacement pairs the longest matching key is replaced at each position
+// and replaced text is never searched again; empty keys are ignored.
+// strtr(string $string, string $from, string $to): string
+// strtr(string $string, array $replace_pairs): string
+func Strtr(str *types.Value, args ...*types.Value) (*types.Value, error) {
+	s := str.ToString()
+	if len(args) == 2 && args[1].IsNull() {
+		args = args[:1] // strtr($string, $replace_pairs, null)
+	}
+	switch len(args) {
+	case 1:
+		pairs := args[0]
+		if !pairs.IsArray() {
+			return nil, &Error{Class: "TypeError", Message: "strtr(): Argument #2 ($from) must be of type array, " + pairs.TypeName() + " given"}
+		}
+		return types.NewString(replacePairs(s, pairs.ToArray())), nil
+	case 2:
+		from, to := args[0].ToString(), args[1].ToString()
+		size := min(len(from), len(to))
+		if size == 0 {
+			return types.NewString(s), nil
+		}
+		var table [256]byte
+		for i := range table {
+			table[i] = byte(i)
+		}
+		for i := 0; i < size; i++ {
+			table[from[i]] = to[i]
+		}
+		b := []byte(s)
+		for i, c := range b {
+			b[i] = table[c]
+		}
+		return types.NewString(string(b)), nil
+	}
+	return nil, &Error{Class: "ArgumentCountError", Message: "strtr() expects at least 2 arguments, 1 given"}
+}
+
+// replacePairs replaces the keys of pairs found in s by their values,
+// longest key first
+func replacePairs(s string, pairs *types.Array) string {
+	replacements := map[string]string{}
+	var lengths []int
+	seen := map[int]bool{}
+	pairs.Each(func(key, value *types.Value) bool {
+		k := key.ToString()
+		if k == "" {
+			return true
+		}
+		replacements[k] = value.Deref().ToString()
+		if !seen[len(k)] {
+			seen[len(k)] = true
+			lengths = append(lengths, len(k))
+		}
+		return true
+	})
+	if len(replacements) == 0 {
+		return s
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(lengths)))
+
+	var result strings.Builder
+	for i := 0; i < len(s); {
+		matched := false
+		for _, length := range lengths {
+			if i+length > len(s) {
+				continue
+			}
+			if replacement, ok := replacements[s[i:i+length]]; ok {
+				result.WriteString(replacement)
+				i += length
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			result.WriteByte(s[i])
+			i++
+		}
+	}
+	return result.String()
+}
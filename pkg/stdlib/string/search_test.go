@@ -0,0 +1,104 @@
+package string
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestStrContainsStartsEnds(t *testing.T) {
+	haystack := types.NewString("The lazy fox")
+	tests := []struct {
+		fn       func(haystack, needle *types.Value) *types.Value
+		needle   string
+		expected bool
+	}{
+		{StrContains, "lazy", true},
+		{StrContains, "Lazy", false},
+		{StrContains, "", true},
+		{StrStartsWith, "The", true},
+		{StrStartsWith, "fox", false},
+		{StrStartsWith, "", true},
+		{StrEndsWith, "fox", true},
+		{StrEndsWith, "The", false},
+		{StrEndsWith, "", true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.fn(haystack, types.NewString(tt.needle)).ToBool(); got != tt.expected {
+			t.Errorf("needle %q: expected %v, got %v", tt.needle, tt.expected, got)
+		}
+	}
+}
+
+func TestSubstrCount(t *testing.T) {
+	text := types.NewString("hello hello hello")
+	tests := []struct {
+		needle   string
+		args     []*types.Value
+		expected int64
+	}{
+		{"hello", nil, 3},
+		{"ll", []*types.Value{types.NewInt(3)}, 2},
+		{"hello", []*types.Value{types.NewInt(1), types.NewInt(10)}, 1},
+		{"hello", []*types.Value{types.NewInt(-5)}, 1},
+		{"hello", []*types.Value{types.NewInt(0), types.NewInt(-1)}, 2},
+		{"hello", []*types.Value{types.NewInt(0), types.NewNull()}, 3},
+	}
+	for _, tt := range tests {
+		result, err := SubstrCount(text, types.NewString(tt.needle), tt.args...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.ToInt() != tt.expected {
+			t.Errorf("SubstrCount(%q, %v) = %d, want %d", tt.needle, tt.args, result.ToInt(), tt.expected)
+		}
+	}
+
+	if result, _ := SubstrCount(types.NewString("aaa"), types.NewString("aa")); result.ToInt() != 1 {
+		t.Errorf("Expected occurrences not to overlap, got %d", result.ToInt())
+	}
+
+	errors := []struct {
+		needle  string
+		args    []*types.Value
+		message string
+	}{
+		{"", nil, "substr_count(): Argument #2 ($needle) cannot be empty"},
+		{"a", []*types.Value{types.NewInt(18)}, "substr_count(): Argument #3 ($offset) must be contained in argument #1 ($haystack)"},
+		{"a", []*types.Value{types.NewInt(10), types.NewInt(8)}, "substr_count(): Argument #4 ($length) must be contained in argument #1 ($haystack)"},
+	}
+	for _, tt := range errors {
+		_, err := SubstrCount(text, types.NewString(tt.needle), tt.args...)
+		if e, ok := err.(*Error); !ok || e.Class != "ValueError" || e.Message != tt.message {
+			t.Errorf("Expected ValueError %q, got %v", tt.message, err)
+		}
+	}
+}
+
+func TestStrtr(t *testing.T) {
+	result, _ := Strtr(types.NewString("Hi all, I said"), types.NewString("ai"), types.NewString("eo"))
+	if result.ToString() != "Ho ell, I seod" {
+		t.Errorf("Expected bytes to be translated, got %q", result.ToString())
+	}
+
+	result, _ = Strtr(types.NewString("abc"), types.NewString("ab"), types.NewString("x"))
+	if result.ToString() != "xbc" {
+		t.Errorf("Expected the longer from to be cut, got %q", result.ToString())
+	}
+
+	pairs := types.NewEmptyArray()
+	pairs.Set(types.NewString("Hi"), types.NewString("Hello"))
+	pairs.Set(types.NewString("Hello"), types.NewString("Hi"))
+	pairs.Set(types.NewString("H"), types.NewString("-"))
+	pairs.Set(types.NewString(""), types.NewString("ignored"))
+	result, _ = Strtr(types.NewString("Hi all, Hello H"), types.NewArray(pairs))
+	if result.ToString() != "Hello all, Hi -" {
+		t.Errorf("Expected the longest keys to be replaced once, got %q", result.ToString())
+	}
+
+	_, err := Strtr(types.NewString("abc"), types.NewString("ab"))
+	if e, ok := err.(*Error); !ok || e.Class != "TypeError" {
+		t.Errorf("Expected a TypeError for a string of pairs, got %v", err)
+	}
+}
@@ -0,0 +1,412 @@
+package string
+
+import (
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// String Similarity
+// ============================================================================
+
+// Levenshtein calculates the Levenshtein distance between two strings:
+// the minimal cost of the byte insertions, replacements and deletions
+// turning the first into the second. Each operation costs 1 unless costs
+// are given.
+// levenshtein(string $string1, string $string2, int $insertion_cost = 1, int $replacement_cost = 1, int $deletion_cost = 1): int
+func Levenshtein(string1, string2 *types.Value, costs ...*types.Value) *types.Value {
+	s1, s2 := string1.ToString(), string2.ToString()
+	insertCost, replaceCost, deleteCost := int64(1), int64(1), int64(1)
+	for i, cost := range []*int64{&insertCost, &replaceCost, &deleteCost} {
+		if i < len(costs) && costs[i] != nil {
+			*cost = costs[i].ToInt()
+		}
+	}
+
+	if len(s1) == 0 {
+		return types.NewInt(int64(len(s2)) * insertCost)
+	}
+	if len(s2) == 0 {
+		return types.NewInt(int64(len(s1)) * deleteCost)
+	}
+
+	previous := make([]int64, len(s2)+1)
+	current := make([]int64, len(s2)+1)
+	for j := range previous {
+		previous[j] = int64(j) * insertCost
+	}
+	for i := 0; i < len(s1); i++ {
+		current[0] = previous[0] + deleteCost
+		for j := 0; j < len(s2); j++ {
+			cost := previous[j]
+			if s1[i] != s2[j] {
+				cost += replaceCost
+			}
+			cost = min(cost, previous[j+1]+deleteCost, current[j]+insertCost)
+			current[j+1] = cost
+		}
+		previous, current = current, previous
+	}
+	return types.NewInt(previous[len(s2)])
+}
+
+// SimilarText calculates the similarity of two strings: the number of
+// matching bytes, found by taking the longest common substring and
+// recursing into the parts on its left and right, and that number as a
+// percentage of the average length
+// similar_text(string $string1, string $string2, float &$percent = null): int
+func SimilarText(string1, string2 *types.Value) (*types.Value, *types.Value) {
+	s1, s2 := string1.ToString(), string2.ToString()
+	if len(s1)+len(s2) == 0 {
+		return types.NewInt(0), types.NewFloat(0)
+	}
+	similar := similarChars(s1, s2)
+	percent := float64(similar) * 2 * 100 / float64(len(s1)+len(s2))
+	return types.NewInt(int64(similar)), types.NewFloat(percent)
+}
+
+// similarChars counts the bytes similar_text() considers matching
+func similarChars(s1, s2 string) int {
+	pos1, pos2, longest := 0, 0, 0
+	for i := 0; i < len(s1); i++ {
+		for j := 0; j < len(s2); j++ {
+			n := 0
+			for i+n < len(s1) && j+n < len(s2) && s1[i+n] == s2[j+n] {
+				n++
+			}
+			if n > longest {
+				pos1, pos2, longest = i, j, n
+			}
+		}
+	}
+	if longest == 0 {
+		return 0
+	}
+
+	sum := longest
+	if pos1 > 0 && pos2 > 0 {
+		sum += similarChars(s1[:pos1], s2[:pos2])
+	}
+	if pos1+longest < len(s1) && pos2+longest < len(s2) {
+		sum += similarChars(s1[pos1+longest:], s2[pos2+longest:])
+	}
+	return sum
+}
+
+// ============================================================================
+// Phonetic Keys
+// ============================================================================
+
+// soundexCodes are the soundex digits of the letters A to Z; 0 marks the
+// letters that are not coded
+const soundexCodes = "01230120022455012623010202"
+
+// Soundex calculates the soundex key of a string: its first letter
+// followed by three digits coding the following consonants. Non-letters
+// are ignored; the empty string has an empty key.
+// soundex(string $string): string
+func Soundex(str *types.Value) *types.Value {
+	s := str.ToString()
+	key := make([]byte, 0, 4)
+	var last byte
+	for i := 0; i < len(s) && len(key) < 4; i++ {
+		c := upper(s[i])
+		if c < 'A' || c > 'Z' {
+			continue
+		}
+		code := soundexCodes[c-'A']
+		if len(key) == 0 {
+			key = append(key, c)
+		} else if code != last && code != '0' {
+			key = append(key, code)
+		}
+		last = code
+	}
+	if len(key) == 0 {
+		return types.NewString("")
+	}
+	for len(key) < 4 {
+		key = append(key, '0')
+	}
+	return types.NewString(string(key))
+}
+
+// Metaphone calculates the metaphone key of a string, a phonetic key of
+// English pronunciation more precise than soundex: "Smith" and "Smyth"
+// both give "SM0". A positive maxPhonemes limits the length of the
+// key.
+// metaphone(string $string, int $max_phonemes = 0): string
+func Metaphone(str *types.Value, maxPhonemes ...*types.Value) (*types.Value, error) {
+	limit := 0
+	if len(maxPhonemes) > 0 && maxPhonemes[0] != nil {
+		limit = int(maxPhonemes[0].ToInt())
+		if limit < 0 {
+			return nil, &Error{Class: "ValueError", Message: "metaphone(): Argument #2 ($max_phonemes) must be greater than or equal to 0"}
+		}
+	}
+	return types.NewString(metaphone(asciiUpper(str.ToString()), limit)), nil
+}
+
+// metaphone computes the metaphone key of an upper-case word; '0' stands
+// for "th" and 'X' for "sh"
+func metaphone(word string, limit int) string {
+	at := func(i int) byte {
+		if i < 0 || i >= len(word) {
+			return 0
+		}
+		return word[i]
+	}
+	isVowel := func(c byte) bool { return strings.IndexByte("AEIOU", c) >= 0 }
+	makesSoft := func(c byte) bool { return c == 'E' || c == 'I' || c == 'Y' }
+	affectsH := func(c byte) bool { return strings.IndexByte("CGPST", c) >= 0 }
+	noGhToF := func(c byte) bool { return c == 'B' || c == 'D' || c == 'H' }
+
+	var key []byte
+	full := func() bool { return limit > 0 && len(key) >= limit }
+
+	// Skip leading non-letters
+	i := 0
+	for i < len(word) && !isAlpha(word[i]) {
+		i++
+	}
+	if i == len(word) {
+		return ""
+	}
+
+	// Initial letters
+	switch c, next := at(i), at(i+1); c {
+	case 'A':
+		if next == 'E' {
+			key = append(key, 'E')
+			i += 2
+		} else {
+			key = append(key, 'A')
+			i++
+		}
+	case 'G', 'K', 'P':
+		if next == 'N' {
+			key = append(key, 'N')
+			i += 2
+		}
+	case 'W':
+		if next == 'R' {
+			key = append(key, 'R')
+			i += 2
+		} else if next == 'H' || isVowel(next) {
+			key = append(key, 'W')
+			i += 2
+		}
+	case 'X':
+		key = append(key, 'S')
+		i++
+	case 'E', 'I', 'O', 'U':
+		key = append(key, c)
+		i++
+	}
+
+	for ; i < len(word) && !full(); i++ {
+		c := word[i]
+		if !isAlpha(c) {
+			continue
+		}
+		prev, next, afterNext := at(i-1), at(i+1), at(i+2)
+		// Doubled letters count once, except C
+		if c == prev && c != 'C' {
+			continue
+		}
+
+		skip := 0
+		switch c {
+		case 'B':
+			// Silent in a final MB
+			if !(prev == 'M' && next == 0) {
+				key = append(key, 'B')
+			}
+		case 'C':
+			switch {
+			case makesSoft(next):
+				if next == 'I' && afterNext == 'A' {
+					key = append(key, 'X')
+				} else if prev != 'S' {
+					key = append(key, 'S')
+				}
+			case next == 'H':
+				if afterNext == 'R' || prev == 'S' {
+					key = append(key, 'K')
+				} else {
+					key = append(key, 'X')
+				}
+				skip++
+			default:
+				key = append(key, 'K')
+			}
+		case 'D':
+			if next == 'G' && makesSoft(afterNext) {
+				key = append(key, 'J')
+				skip++
+			} else {
+				key = append(key, 'T')
+			}
+		case 'G':
+			switch {
+			case next == 'H':
+				if !(noGhToF(at(i-3)) || at(i-4) == 'H') {
+					key = append(key, 'F')
+					skip++
+				}
+			case next == 'N':
+				if isAlpha(afterNext) && !(afterNext == 'E' && at(i+3) == 'D') {
+					key = append(key, 'K')
+				}
+			case makesSoft(next) && prev != 'G':
+				key = append(key, 'J')
+			default:
+				key = append(key, 'K')
+			}
+		case 'H':
+			if isVowel(next) && !affectsH(prev) {
+				key = append(key, 'H')
+			}
+		case 'K':
+			if prev != 'C' {
+				key = append(key, 'K')
+			}
+		case 'P':
+			if next == 'H' {
+				key = append(key, 'F')
+			} else {
+				key = append(key, 'P')
+			}
+		case 'Q':
+			key = append(key, 'K')
+		case 'S':
+			switch {
+			case next == 'I' && (afterNext == 'O' || afterNext == 'A'):
+				key = append(key, 'X')
+			case next == 'H':
+				key = append(key, 'X')
+				skip++
+			case next == 'C' && afterNext == 'H' && at(i+3) == 'W':
+				key = append(key, 'X')
+				skip += 2
+			default:
+				key = append(key, 'S')
+			}
+		case 'T':
+			switch {
+			case next == 'I' && (afterNext == 'O' || afterNext == 'A'):
+				key = append(key, 'X')
+			case next == 'H':
+				key = append(key, '0')
+				skip++
+			case !(next == 'C' && afterNext == 'H'):
+				key = append(key, 'T')
+			}
+		case 'V':
+			key = append(key, 'F')
+		case 'W', 'Y':
+			if isVowel(next) {
+				key = append(key, c)
+			}
+		case 'X':
+			key = append(key, 'K', 'S')
+		case 'Z':
+			key = append(key, 'S')
+		case 'F', 'J', 'L', 'M', 'N', 'R':
+			key = append(key, c)
+		}
+		i += skip
+	}
+
+	if limit > 0 && len(key) > limit {
+		key = key[:limit]
+	}
+	return string(key)
+}
+
+// ============================================================================
+// Word Counting
+// ============================================================================
+
+// StrWordCount counts the words of a string, or returns them: format 1
+// gives the list of words, format 2 the words keyed by their offset.
+// Words are runs of letters, apostrophes and hyphens (not leading, nor a
+// trailing hyphen), plus the characters of characters, which may contain
+// ranges such as "0..9".
+// str_word_count(string $string, int $format = 0, ?string $characters = null): array|int
+func StrWordCount(str *types.Value, args ...*types.Value) (*types.Value, error) {
+	s := str.ToString()
+	format := int64(0)
+	if len(args) > 0 && args[0] != nil {
+		format = args[0].ToInt()
+	}
+	if format < 0 || format > 2 {
+		return nil, &Error{Class: "ValueError", Message: "str_word_count(): Argument #2 ($format) must be a valid format value"}
+	}
+	var extra [256]bool
+	if len(args) > 1 && args[1] != nil && !args[1].IsNull() {
+		extra = charMask(args[1].ToString())
+	}
+
+	words := types.NewEmptyArray()
+	count := int64(0)
+	start, end := 0, len(s)
+	if start < end && ((s[start] == '\'' && !extra['\'']) || (s[start] == '-' && !extra['-'])) {
+		start++
+	}
+	if start < end && s[end-1] == '-' && !extra['-'] {
+		end--
+	}
+
+	for p := start; p < end; p++ {
+		from := p
+		for p < end && (isAlpha(s[p]) || extra[s[p]] || s[p] == '\'' || s[p] == '-') {
+			p++
+		}
+		if p == from {
+			continue
+		}
+		switch format {
+		case 1:
+			words.Append(types.NewString(s[from:p]))
+		case 2:
+			words.Set(types.NewInt(int64(from)), types.NewString(s[from:p]))
+		default:
+			count++
+		}
+	}
+
+	if format == 0 {
+		return types.NewInt(count), nil
+	}
+	return types.NewArray(words), nil
+}
+
+// charMask returns the set of bytes of a character list, where "a..z"
+// stands for the range of bytes from a to z
+func charMask(chars string) [256]bool {
+	var mask [256]bool
+	for i := 0; i < len(chars); i++ {
+		if i+3 < len(chars) && chars[i+1] == '.' && chars[i+2] == '.' && chars[i+3] >= chars[i] {
+			for c := int(chars[i]); c <= int(chars[i+3]); c++ {
+				mask[c] = true
+			}
+			i += 3
+			continue
+		}
+		mask[chars[i]] = true
+	}
+	return mask
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func upper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
@@ -0,0 +1,126 @@
+package string
+
+import (
+	"math"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		costs    []*types.Value
+		expected int64
+	}{
+		{"kitten", "sitting", nil, 3},
+		{"", "abc", nil, 3},
+		{"abc", "", nil, 3},
+		{"same", "same", nil, 0},
+		{"abc", "abd", []*types.Value{types.NewInt(1), types.NewInt(5), types.NewInt(1)}, 2},
+		{"", "ab", []*types.Value{types.NewInt(4)}, 8},
+	}
+	for _, tt := range tests {
+		got := Levenshtein(types.NewString(tt.a), types.NewString(tt.b), tt.costs...).ToInt()
+		if got != tt.expected {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestSimilarText(t *testing.T) {
+	similar, percent := SimilarText(types.NewString("World"), types.NewString("Word"))
+	if similar.ToInt() != 4 || math.Abs(percent.ToFloat()-88.888888) > 1e-4 {
+		t.Errorf("Expected 4 and 88.89%%, got %v and %v", similar, percent)
+	}
+
+	// The first longest common substring splits the strings, so the
+	// order of the arguments matters
+	similar, percent = SimilarText(types.NewString("bafoobar"), types.NewString("barfoo"))
+	if similar.ToInt() != 5 || math.Abs(percent.ToFloat()-71.428571) > 1e-4 {
+		t.Errorf("Expected 5 and 71.43%%, got %v and %v", similar, percent)
+	}
+	if similar, _ = SimilarText(types.NewString("barfoo"), types.NewString("bafoobar")); similar.ToInt() != 3 {
+		t.Errorf("Expected 3 similar bytes, got %v", similar)
+	}
+
+	if similar, percent = SimilarText(types.NewString(""), types.NewString("")); similar.ToInt() != 0 || percent.ToFloat() != 0 {
+		t.Errorf("Expected empty strings to have no similarity, got %v and %v", similar, percent)
+	}
+}
+
+func TestSoundex(t *testing.T) {
+	tests := map[string]string{
+		"Robert":  "R163",
+		"Rupert":  "R163",
+		"Tymczak": "T522",
+		"Pfister": "P236",
+		"Lee":     "L000",
+		" lloyd!": "L300",
+		"":        "",
+		"1234":    "",
+	}
+	for input, expected := range tests {
+		if got := Soundex(types.NewString(input)).ToString(); got != expected {
+			t.Errorf("Soundex(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
+
+func TestMetaphone(t *testing.T) {
+	tests := map[string]string{
+		"Smith":   "SM0",
+		"Smyth":   "SM0",
+		"physics": "FSKS",
+		"Thumb":   "0M",
+		"Knight":  "NFT",
+		"Wright":  "RFT",
+		"Xavier":  "SFR",
+		"ace":     "AS",
+		"school":  "SKL",
+		"judge":   "JJ",
+		"":        "",
+	}
+	for input, expected := range tests {
+		result, err := Metaphone(types.NewString(input))
+		if err != nil || result.ToString() != expected {
+			t.Errorf("Metaphone(%q) = %v, want %q (%v)", input, result, expected, err)
+		}
+	}
+
+	if result, _ := Metaphone(types.NewString("physics"), types.NewInt(2)); result.ToString() != "FS" {
+		t.Errorf("Expected the key to be limited to 2 phonemes, got %v", result)
+	}
+	if _, err := Metaphone(types.NewString("a"), types.NewInt(-1)); err == nil {
+		t.Error("Expected a ValueError for a negative limit")
+	}
+}
+
+func TestStrWordCount(t *testing.T) {
+	text := types.NewString("Hello fri3nd, you're looking good-ish today -")
+
+	count, err := StrWordCount(text)
+	if err != nil || count.ToInt() != 7 {
+		t.Errorf("Expected 7 words, got %v (%v)", count, err)
+	}
+
+	words, _ := StrWordCount(text, types.NewInt(1))
+	expected := []string{"Hello", "fri", "nd", "you're", "looking", "good-ish", "today"}
+	if words.ToArray().Len() != len(expected) {
+		t.Fatalf("Expected %v, got %d words", expected, words.ToArray().Len())
+	}
+	for i, want := range expected {
+		if word, _ := words.ToArray().Get(types.NewInt(int64(i))); word.ToString() != want {
+			t.Errorf("Word %d: expected %q, got %v", i, want, word)
+		}
+	}
+
+	byOffset, _ := StrWordCount(text, types.NewInt(2), types.NewString("0..9"))
+	if word, _ := byOffset.ToArray().Get(types.NewInt(6)); word.ToString() != "fri3nd" {
+		t.Errorf("Expected digits to be word characters keyed by offset, got %v", word)
+	}
+
+	if _, err := StrWordCount(text, types.NewInt(3)); err == nil {
+		t.Error("Expected a ValueError for an invalid format")
+	}
+}
@@ -2,7 +2,9 @@ package varfuncs
 
 import (
 	"fmt"
+	"math"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/krizos/php-go/pkg/types"
@@ -12,119 +14,165 @@ import (
 // Variable Dumping Functions
 // ============================================================================
 
+// PropertySource returns the properties shown when dumping an object.
+// Engines that can run user code supply one that honours __debugInfo.
+type PropertySource func(obj *types.Object) ([]types.DebugProperty, error)
+
+// RawProperties is the default PropertySource: the object's own properties
+func RawProperties(obj *types.Object) ([]types.DebugProperty, error) {
+	return obj.DebugProperties(), nil
+}
+
+// Dumper renders values in var_dump, print_r and var_export format
+type Dumper struct {
+	Properties PropertySource       // Object property source (nil means RawProperties)
+	Warning    func(message string) // Receives var_export warnings (nil discards them)
+}
+
+// NewDumper creates a dumper using the given property source
+func NewDumper(source PropertySource) *Dumper {
+	return &Dumper{Properties: source}
+}
+
+// objectProperties fetches the debug view of an object
+func (d *Dumper) objectProperties(obj *types.Object) ([]types.DebugProperty, error) {
+	if d.Properties == nil {
+		return RawProperties(obj)
+	}
+	return d.Properties(obj)
+}
+
 // VarDump prints structured information about one or more variables
 // var_dump(mixed ...$vars): void
 func VarDump(values ...*types.Value) *types.Value {
+	d := &Dumper{}
 	for _, val := range values {
-		dumpValue(val, 0, make(map[interface{}]bool))
+		output, _ := d.VarDump(val)
+		fmt.Print(output)
 	}
 	return types.NewNull()
 }
 
-// dumpValue recursively dumps a value with indentation
-func dumpValue(val *types.Value, indent int, visited map[interface{}]bool) {
+// VarDump renders a value in var_dump format
+func (d *Dumper) VarDump(val *types.Value) (string, error) {
+	var out strings.Builder
+	err := d.dumpValue(&out, val, 0, make(map[interface{}]bool))
+	return out.String(), err
+}
+
+// dumpValue recursively dumps a value with indentation.
+// visited holds the containers on the current path, so shared
+// (non-cyclic) values are dumped in full every time they appear.
+func (d *Dumper) dumpValue(out *strings.Builder, val *types.Value, indent int, visited map[interface{}]bool) error {
 	prefix := strings.Repeat("  ", indent)
+	val = val.Deref()
 
 	switch val.Type() {
 	case types.TypeNull:
-		fmt.Printf("%sNULL\n", prefix)
+		fmt.Fprintf(out, "%sNULL\n", prefix)
 
 	case types.TypeBool:
 		if val.ToBool() {
-			fmt.Printf("%sbool(true)\n", prefix)
+			fmt.Fprintf(out, "%sbool(true)\n", prefix)
 		} else {
-			fmt.Printf("%sbool(false)\n", prefix)
+			fmt.Fprintf(out, "%sbool(false)\n", prefix)
 		}
 
 	case types.TypeInt:
-		fmt.Printf("%sint(%d)\n", prefix, val.ToInt())
+		fmt.Fprintf(out, "%sint(%d)\n", prefix, val.ToInt())
 
 	case types.TypeFloat:
-		fmt.Printf("%sfloat(%g)\n", prefix, val.ToFloat())
+		fmt.Fprintf(out, "%sfloat(%s)\n", prefix, types.FormatFloat(val.ToFloat(), types.SerializePrecision()))
 
 	case types.TypeString:
 		str := val.ToString()
-		fmt.Printf("%sstring(%d) \"%s\"\n", prefix, len(str), str)
+		fmt.Fprintf(out, "%sstring(%d) \"%s\"\n", prefix, len(str), str)
 
 	case types.TypeArray:
 		arr := val.ToArray()
-		fmt.Printf("%sarray(%d) {\n", prefix, arr.Len())
 
-		// Collect and sort keys for consistent output
-		var keys []*types.Value
-		arr.Each(func(key, _ *types.Value) bool {
-			keys = append(keys, key)
-			return true
-		})
+		// Check for circular reference
+		if visited[arr] {
+			fmt.Fprintf(out, "%s*RECURSION*\n", prefix)
+			return nil
+		}
+		visited[arr] = true
+		defer delete(visited, arr)
 
-		// Dump each element
-		idx := 0
+		fmt.Fprintf(out, "%sarray(%d) {\n", prefix, arr.Len())
+
+		var err error
 		arr.Each(func(key, value *types.Value) bool {
 			// Print key
 			if key.Type() == types.TypeInt {
-				fmt.Printf("%s  [%d]=>\n", prefix, key.ToInt())
+				fmt.Fprintf(out, "%s  [%d]=>\n", prefix, key.ToInt())
 			} else {
-				fmt.Printf("%s  [\"%s\"]=>\n", prefix, key.ToString())
-			}
-
-			// Check for circular reference
-			if value.Type() == types.TypeArray || value.Type() == types.TypeObject {
-				// Use pointer as identifier for visited tracking
-				ptr := fmt.Sprintf("%p", value)
-				if visited[ptr] {
-					fmt.Printf("%s    *RECURSION*\n", prefix)
-					return true
-				}
-				visited[ptr] = true
+				fmt.Fprintf(out, "%s  [\"%s\"]=>\n", prefix, key.ToString())
 			}
 
 			// Print value
-			dumpValue(value, indent+1, visited)
-
-			idx++
-			return true
+			err = d.dumpValue(out, value, indent+1, visited)
+			return err == nil
 		})
+		if err != nil {
+			return err
+		}
 
-		fmt.Printf("%s}\n", prefix)
+		fmt.Fprintf(out, "%s}\n", prefix)
 
 	case types.TypeObject:
 		obj := val.ToObject()
-		className := obj.ClassName
 
 		// Check for circular reference
-		ptr := fmt.Sprintf("%p", obj)
-		if visited[ptr] {
-			fmt.Printf("%sobject(%s)#%d *RECURSION*\n", prefix, className, obj.ObjectID)
-			return
+		if visited[obj] {
+			fmt.Fprintf(out, "%s*RECURSION*\n", prefix)
+			return nil
 		}
-		visited[ptr] = true
+		visited[obj] = true
+		defer delete(visited, obj)
 
-		// Get property count
-		propCount := len(obj.ClassEntry.Properties)
-		fmt.Printf("%sobject(%s)#%d (%d) {\n", prefix, className, obj.ObjectID, propCount)
+		props, err := d.objectProperties(obj)
+		if err != nil {
+			return err
+		}
 
-		// Dump properties
-		for name, propDef := range obj.ClassEntry.Properties {
-			visibility := propDef.Visibility.String()
+		fmt.Fprintf(out, "%sobject(%s)#%d (%d) {\n", prefix, obj.ClassName, obj.ObjectID, len(props))
 
-			// Get property value (no access context needed for var_dump)
-			value, _ := obj.GetProperty(name, nil)
-			fmt.Printf("%s  [\"%s\":%s]=>\n", prefix, name, visibility)
-			dumpValue(value, indent+1, visited)
+		// Dump properties with their visibility annotations
+		for _, prop := range props {
+			fmt.Fprintf(out, "%s  [%s]=>\n", prefix, dumpPropertyKey(prop))
+			if err := d.dumpValue(out, prop.Value, indent+1, visited); err != nil {
+				return err
+			}
 		}
 
-		fmt.Printf("%s}\n", prefix)
+		fmt.Fprintf(out, "%s}\n", prefix)
 
 	case types.TypeResource:
 		res := val.ToResource()
-		if res.IsValid() {
-			fmt.Printf("%sresource(%d) of type (%s)\n", prefix, res.ID(), res.Type())
-		} else {
-			fmt.Printf("%sresource(%d) of type (%s) (closed)\n", prefix, res.ID(), res.Type())
-		}
+		fmt.Fprintf(out, "%sresource(%d) of type (%s)\n", prefix, res.ID(), res.TypeName())
 
 	default:
-		fmt.Printf("%sunknown type\n", prefix)
+		fmt.Fprintf(out, "%sunknown type\n", prefix)
+	}
+
+	return nil
+}
+
+// dumpPropertyKey formats a property key in var_dump style:
+// "name", "name":protected or "name":"Class":private
+func dumpPropertyKey(prop types.DebugProperty) string {
+	if prop.Key.Type() == types.TypeInt {
+		return fmt.Sprintf("%d", prop.Key.ToInt())
+	}
+
+	switch prop.Visibility {
+	case types.VisibilityProtected:
+		return fmt.Sprintf("\"%s\":protected", prop.Key.ToString())
+	case types.VisibilityPrivate:
+		return fmt.Sprintf("\"%s\":\"%s\":private", prop.Key.ToString(), prop.Class)
+	default:
+		return fmt.Sprintf("\"%s\"", prop.Key.ToString())
 	}
 }
 
@@ -136,10 +184,7 @@ func PrintR(val *types.Value, returnOutput ...*types.Value) *types.Value {
 		shouldReturn = returnOutput[0].ToBool()
 	}
 
-	var output strings.Builder
-	printValue(&output, val, 0, make(map[interface{}]bool))
-
-	result := output.String()
+	result, _ := (&Dumper{}).PrintR(val)
 	if shouldReturn {
 		return types.NewString(result)
 	}
@@ -148,102 +193,117 @@ func PrintR(val *types.Value, returnOutput ...*types.Value) *types.Value {
 	return types.NewBool(true)
 }
 
-// printValue recursively prints a value in print_r format
-func printValue(out *strings.Builder, val *types.Value, indent int, visited map[interface{}]bool) {
-	prefix := strings.Repeat("    ", indent)
+// PrintR renders a value in print_r format
+func (d *Dumper) PrintR(val *types.Value) (string, error) {
+	var out strings.Builder
+	err := d.printValue(&out, val, 0, make(map[interface{}]bool))
+	return out.String(), err
+}
+
+// printEntry is an array element or property in print_r format
+type printEntry struct {
+	key   string
+	value *types.Value
+}
+
+// printValue prints a value in print_r format. Scalars print as echo
+// would; the entries of arrays and objects are indented by indent spaces.
+func (d *Dumper) printValue(out *strings.Builder, val *types.Value, indent int, visited map[interface{}]bool) error {
+	val = val.Deref()
 
 	switch val.Type() {
 	case types.TypeNull:
-		// print_r doesn't show anything for null
+		// print_r shows nothing for null
 
 	case types.TypeBool:
 		if val.ToBool() {
 			out.WriteString("1")
-		} else {
-			// print_r shows nothing for false (PHP quirk)
 		}
 
 	case types.TypeInt:
-		out.WriteString(fmt.Sprintf("%d", val.ToInt()))
+		out.WriteString(strconv.FormatInt(val.ToInt(), 10))
 
 	case types.TypeFloat:
-		out.WriteString(fmt.Sprintf("%g", val.ToFloat()))
+		out.WriteString(types.FormatFloat(val.ToFloat(), types.Precision()))
 
 	case types.TypeString:
 		out.WriteString(val.ToString())
 
 	case types.TypeArray:
 		arr := val.ToArray()
-
-		// Check for circular reference
-		ptr := fmt.Sprintf("%p", arr)
-		if visited[ptr] {
+		out.WriteString("Array\n")
+		if visited[arr] {
 			out.WriteString(" *RECURSION*")
-			return
+			return nil
 		}
-		visited[ptr] = true
-
-		out.WriteString("Array\n")
-		out.WriteString(prefix + "(\n")
+		visited[arr] = true
+		defer delete(visited, arr)
 
+		entries := make([]printEntry, 0, arr.Len())
 		arr.Each(func(key, value *types.Value) bool {
-			// Print key
-			if key.Type() == types.TypeInt {
-				out.WriteString(fmt.Sprintf("%s    [%d] => ", prefix, key.ToInt()))
-			} else {
-				out.WriteString(fmt.Sprintf("%s    [%s] => ", prefix, key.ToString()))
-			}
-
-			// Print value
-			if value.Type() == types.TypeArray || value.Type() == types.TypeObject {
-				printValue(out, value, indent+1, visited)
-			} else {
-				printValue(out, value, 0, visited)
-				out.WriteString("\n")
-			}
-
+			entries = append(entries, printEntry{key.ToString(), value})
 			return true
 		})
-
-		out.WriteString(prefix + ")\n")
+		return d.printEntries(out, entries, indent, visited)
 
 	case types.TypeObject:
 		obj := val.ToObject()
-		className := obj.ClassName
-
-		// Check for circular reference
-		ptr := fmt.Sprintf("%p", obj)
-		if visited[ptr] {
+		out.WriteString(obj.ClassName + " Object\n")
+		if visited[obj] {
 			out.WriteString(" *RECURSION*")
-			return
+			return nil
 		}
-		visited[ptr] = true
-
-		out.WriteString(fmt.Sprintf("%s Object\n", className))
-		out.WriteString(prefix + "(\n")
+		visited[obj] = true
+		defer delete(visited, obj)
 
-		// Print properties
-		for name, _ := range obj.ClassEntry.Properties {
-			value, _ := obj.GetProperty(name, nil)
-			out.WriteString(fmt.Sprintf("%s    [%s] => ", prefix, name))
-
-			if value.Type() == types.TypeArray || value.Type() == types.TypeObject {
-				printValue(out, value, indent+1, visited)
-			} else {
-				printValue(out, value, 0, visited)
-				out.WriteString("\n")
-			}
+		props, err := d.objectProperties(obj)
+		if err != nil {
+			return err
 		}
-
-		out.WriteString(prefix + ")\n")
+		entries := make([]printEntry, 0, len(props))
+		for _, prop := range props {
+			entries = append(entries, printEntry{printPropertyKey(prop), prop.Value})
+		}
+		return d.printEntries(out, entries, indent, visited)
 
 	case types.TypeResource:
-		res := val.ToResource()
-		out.WriteString(fmt.Sprintf("Resource id #%d", res.ID()))
+		fmt.Fprintf(out, "Resource id #%d", val.ToResource().ID())
 
 	default:
 		out.WriteString("unknown")
 	}
+
+	return nil
+}
+
+// printEntries prints the parenthesized entries of an array or object;
+// nested containers are indented 8 more spaces, which with the newline
+// after every entry leaves a blank line after them
+func (d *Dumper) printEntries(out *strings.Builder, entries []printEntry, indent int, visited map[interface{}]bool) error {
+	prefix := strings.Repeat(" ", indent)
+	out.WriteString(prefix + "(\n")
+	for _, entry := range entries {
+		fmt.Fprintf(out, "%s    [%s] => ", prefix, entry.key)
+		if err := d.printValue(out, entry.value, indent+8, visited); err != nil {
+			return err
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString(prefix + ")\n")
+	return nil
+}
+
+// printPropertyKey formats a property key in print_r style:
+// name, name:protected or name:Class:private
+func printPropertyKey(prop types.DebugProperty) string {
+	switch prop.Visibility {
+	case types.VisibilityProtected:
+		return prop.Key.ToString() + ":protected"
+	case types.VisibilityPrivate:
+		return prop.Key.ToString() + ":" + prop.Class + ":private"
+	default:
+		return prop.Key.ToString()
+	}
 }
 
 // VarExport outputs or returns a parsable string representation of a variable
@@ -254,10 +314,7 @@ func VarExport(val *types.Value, returnOutput ...*types.Value) *types.Value {
 		shouldReturn = returnOutput[0].ToBool()
 	}
 
-	var output strings.Builder
-	exportValue(&output, val, 0, make(map[interface{}]bool))
-
-	result := output.String()
+	result := (&Dumper{}).VarExport(val)
 	if shouldReturn {
 		return types.NewString(result)
 	}
@@ -266,9 +323,25 @@ func VarExport(val *types.Value, returnOutput ...*types.Value) *types.Value {
 	return types.NewNull()
 }
 
-// exportValue recursively exports a value in valid PHP code format
-func exportValue(out *strings.Builder, val *types.Value, indent int, visited map[interface{}]bool) {
-	prefix := strings.Repeat("  ", indent)
+// VarExport renders a value as PHP code. Objects are exported with their
+// raw properties, as __debugInfo() does not apply to var_export.
+func (d *Dumper) VarExport(val *types.Value) string {
+	var out strings.Builder
+	d.exportValue(&out, val, 1, make(map[interface{}]bool))
+	return out.String()
+}
+
+// warn reports a var_export warning
+func (d *Dumper) warn(message string) {
+	if d.Warning != nil {
+		d.Warning(message)
+	}
+}
+
+// exportValue exports a value at a nesting level starting at 1. Nested
+// arrays and objects start on a new line indented by level-1 spaces.
+func (d *Dumper) exportValue(out *strings.Builder, val *types.Value, level int, visited map[interface{}]bool) {
+	val = val.Deref()
 
 	switch val.Type() {
 	case types.TypeNull:
@@ -282,107 +355,96 @@ func exportValue(out *strings.Builder, val *types.Value, indent int, visited map
 		}
 
 	case types.TypeInt:
-		out.WriteString(fmt.Sprintf("%d", val.ToInt()))
+		n := val.ToInt()
+		if n == math.MinInt64 {
+			// -9223372036854775808 would be parsed as a float
+			fmt.Fprintf(out, "%d-1", n+1)
+			return
+		}
+		out.WriteString(strconv.FormatInt(n, 10))
 
 	case types.TypeFloat:
-		f := val.ToFloat()
-		// Format float to ensure it always has decimal point
-		str := fmt.Sprintf("%g", f)
-		if !strings.Contains(str, ".") && !strings.Contains(str, "e") {
-			str += ".0"
-		}
+		str := types.FormatFloat(val.ToFloat(), types.SerializePrecision())
 		out.WriteString(str)
+		if f := val.ToFloat(); !math.IsInf(f, 0) && !math.IsNaN(f) && !strings.ContainsAny(str, ".E") {
+			out.WriteString(".0")
+		}
 
 	case types.TypeString:
-		// Escape string for PHP
-		str := val.ToString()
-		escaped := strings.ReplaceAll(str, "\\", "\\\\")
-		escaped = strings.ReplaceAll(escaped, "'", "\\'")
-		out.WriteString(fmt.Sprintf("'%s'", escaped))
+		out.WriteString(exportString(val.ToString()))
 
 	case types.TypeArray:
 		arr := val.ToArray()
-
-		// Check for circular reference
-		ptr := fmt.Sprintf("%p", arr)
-		if visited[ptr] {
-			out.WriteString("/* RECURSION */")
+		if visited[arr] {
+			d.warn("var_export does not handle circular references")
+			out.WriteString("NULL")
 			return
 		}
-		visited[ptr] = true
-
-		out.WriteString("array (\n")
+		visited[arr] = true
+		defer delete(visited, arr)
 
-		// Collect keys to sort them (for consistent output)
-		type keyValuePair struct {
-			key   *types.Value
-			value *types.Value
+		if level > 1 {
+			out.WriteString("\n" + strings.Repeat(" ", level-1))
 		}
-		var pairs []keyValuePair
-
+		out.WriteString("array (\n")
 		arr.Each(func(key, value *types.Value) bool {
-			pairs = append(pairs, keyValuePair{key, value})
-			return true
-		})
-
-		// Sort by key if all integer keys
-		allInt := true
-		for _, pair := range pairs {
-			if pair.key.Type() != types.TypeInt {
-				allInt = false
-				break
-			}
-		}
-
-		if allInt {
-			sort.Slice(pairs, func(i, j int) bool {
-				return pairs[i].key.ToInt() < pairs[j].key.ToInt()
-			})
-		}
-
-		for _, pair := range pairs {
-			out.WriteString(prefix + "  ")
-
-			// Print key
-			if pair.key.Type() == types.TypeInt {
-				out.WriteString(fmt.Sprintf("%d => ", pair.key.ToInt()))
+			out.WriteString(strings.Repeat(" ", level+1))
+			if key.Type() == types.TypeInt {
+				out.WriteString(strconv.FormatInt(key.ToInt(), 10))
 			} else {
-				out.WriteString(fmt.Sprintf("'%s' => ", pair.key.ToString()))
+				out.WriteString(exportString(key.ToString()))
 			}
-
-			// Print value
-			exportValue(out, pair.value, indent+1, visited)
+			out.WriteString(" => ")
+			d.exportValue(out, value, level+2, visited)
 			out.WriteString(",\n")
+			return true
+		})
+		if level > 1 {
+			out.WriteString(strings.Repeat(" ", level-1))
 		}
-
-		out.WriteString(prefix + ")")
+		out.WriteString(")")
 
 	case types.TypeObject:
-		// var_export shows stdClass objects as stdClass::__set_state(array(...))
-		// For now, we'll use a simplified representation
 		obj := val.ToObject()
-		className := obj.ClassName
-
-		ptr := fmt.Sprintf("%p", obj)
-		if visited[ptr] {
-			out.WriteString("/* RECURSION */")
+		if visited[obj] {
+			d.warn("var_export does not handle circular references")
+			out.WriteString("NULL")
 			return
 		}
-		visited[ptr] = true
+		visited[obj] = true
+		defer delete(visited, obj)
 
-		out.WriteString(fmt.Sprintf("%s::__set_state(array(\n", className))
-
-		for name, _ := range obj.ClassEntry.Properties {
-			value, _ := obj.GetProperty(name, nil)
-			out.WriteString(fmt.Sprintf("%s  '%s' => ", prefix, name))
-			exportValue(out, value, indent+1, visited)
+		if level > 1 {
+			out.WriteString("\n" + strings.Repeat(" ", level-1))
+		}
+		isStdClass := strings.EqualFold(obj.ClassName, "stdClass")
+		if isStdClass {
+			out.WriteString("(object) array(\n")
+		} else {
+			out.WriteString("\\" + obj.ClassName + "::__set_state(array(\n")
+		}
+		for _, prop := range obj.DebugProperties() {
+			out.WriteString(strings.Repeat(" ", level+2))
+			if prop.Key.Type() == types.TypeInt {
+				out.WriteString(strconv.FormatInt(prop.Key.ToInt(), 10))
+			} else {
+				out.WriteString(exportString(prop.Key.ToString()))
+			}
+			out.WriteString(" => ")
+			d.exportValue(out, prop.Value, level+2, visited)
 			out.WriteString(",\n")
 		}
-
-		out.WriteString(prefix + "))")
+		if level > 1 {
+			out.WriteString(strings.Repeat(" ", level-1))
+		}
+		if isStdClass {
+			out.WriteString(")")
+		} else {
+			out.WriteString("))")
+		}
 
 	case types.TypeResource:
-		// Resources can't be exported
+		d.warn("var_export does not handle resources")
 		out.WriteString("NULL")
 
 	default:
@@ -390,71 +452,18 @@ func exportValue(out *strings.Builder, val *types.Value, indent int, visited map
 	}
 }
 
-// ============================================================================
-// Type Checking Functions
-// ============================================================================
-
-// IsNull checks if a variable is null
-// is_null(mixed $value): bool
-func IsNull(val *types.Value) *types.Value {
-	return types.NewBool(val.Type() == types.TypeNull)
-}
-
-// IsBool checks if a variable is a boolean
-// is_bool(mixed $value): bool
-func IsBool(val *types.Value) *types.Value {
-	return types.NewBool(val.Type() == types.TypeBool)
+// exportString quotes a string as a single-quoted PHP literal; NUL bytes,
+// which such literals cannot hold, are concatenated as "\0"
+func exportString(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "'", "\\'")
+	s = strings.ReplaceAll(s, "\x00", `' . "\0" . '`)
+	return "'" + s + "'"
 }
 
-// IsInt checks if a variable is an integer
-// is_int(mixed $value): bool
-func IsInt(val *types.Value) *types.Value {
-	return types.NewBool(val.Type() == types.TypeInt)
-}
-
-// IsLong is an alias for IsInt
-func IsLong(val *types.Value) *types.Value {
-	return IsInt(val)
-}
-
-// IsInteger is an alias for IsInt
-func IsInteger(val *types.Value) *types.Value {
-	return IsInt(val)
-}
-
-// IsFloat checks if a variable is a float
-// is_float(mixed $value): bool
-func IsFloat(val *types.Value) *types.Value {
-	return types.NewBool(val.Type() == types.TypeFloat)
-}
-
-// IsDouble is an alias for IsFloat
-func IsDouble(val *types.Value) *types.Value {
-	return IsFloat(val)
-}
-
-// IsReal is an alias for IsFloat (deprecated but still in PHP)
-func IsReal(val *types.Value) *types.Value {
-	return IsFloat(val)
-}
-
-// IsString checks if a variable is a string
-// is_string(mixed $value): bool
-func IsString(val *types.Value) *types.Value {
-	return types.NewBool(val.Type() == types.TypeString)
-}
-
-// IsArray checks if a variable is an array
-// is_array(mixed $value): bool
-func IsArray(val *types.Value) *types.Value {
-	return types.NewBool(val.Type() == types.TypeArray)
-}
-
-// IsObject checks if a variable is an object
-// is_object(mixed $value): bool
-func IsObject(val *types.Value) *types.Value {
-	return types.NewBool(val.Type() == types.TypeObject)
-}
+// ============================================================================
+// Resource Functions
+// ============================================================================
 
 // IsResource checks if a variable is a resource
 // is_resource(mixed $value): bool
@@ -467,120 +476,31 @@ func IsResource(val *types.Value) *types.Value {
 	return types.NewBool(res.IsValid())
 }
 
-// IsNumeric checks if a variable is a number or numeric string
-// is_numeric(mixed $value): bool
-func IsNumeric(val *types.Value) *types.Value {
-	switch val.Type() {
-	case types.TypeInt, types.TypeFloat:
-		return types.NewBool(true)
-
-	case types.TypeString:
-		str := val.ToString()
-		// Try to parse as int or float
-		_, errInt := fmt.Sscanf(str, "%d", new(int64))
-		if errInt == nil {
-			return types.NewBool(true)
-		}
-		_, errFloat := fmt.Sscanf(str, "%f", new(float64))
-		return types.NewBool(errFloat == nil)
-
-	default:
-		return types.NewBool(false)
-	}
+// GetResourceType returns the type of a resource
+// get_resource_type(resource $resource): string
+func GetResourceType(val *types.Value) *types.Value {
+	return types.NewString(val.ToResource().TypeName())
 }
 
-// IsScalar checks if a variable is a scalar (int, float, string, or bool)
-// is_scalar(mixed $value): bool
-func IsScalar(val *types.Value) *types.Value {
-	return types.NewBool(val.IsScalar())
+// GetResourceID returns the integer identifier of a resource
+// get_resource_id(resource $resource): int
+func GetResourceID(val *types.Value) *types.Value {
+	return types.NewInt(int64(val.ToResource().ID()))
 }
 
-// IsCallable checks if a variable can be called as a function
-// is_callable(mixed $value): bool
-func IsCallable(val *types.Value) *types.Value {
-	// TODO: Implement proper callable checking when closures are added
-	switch val.Type() {
-	case types.TypeString:
-		// String could be a function name
-		return types.NewBool(true)
-
-	case types.TypeArray:
-		// Array like ['ClassName', 'methodName'] or [$object, 'methodName']
-		arr := val.ToArray()
-		if arr.Len() == 2 {
-			return types.NewBool(true)
+// GetResources returns the active resources, keyed by ID, optionally
+// only those of one type
+// get_resources(?string $type = null): array
+func GetResources(args ...*types.Value) *types.Value {
+	result := types.NewEmptyArray()
+	ids := types.GetActiveResourceIDs()
+	sort.Ints(ids)
+	for _, id := range ids {
+		res, ok := types.GetResourceByID(id)
+		if !ok || (len(args) > 0 && !args[0].IsNull() && res.Type() != args[0].ToString()) {
+			continue
 		}
-		return types.NewBool(false)
-
-	case types.TypeObject:
-		// Objects with __invoke are callable
-		obj := val.ToObject()
-		_, hasInvoke := obj.ClassEntry.Methods["__invoke"]
-		return types.NewBool(hasInvoke)
-
-	default:
-		return types.NewBool(false)
-	}
-}
-
-// IsIterable checks if a variable can be iterated over
-// is_iterable(mixed $value): bool
-func IsIterable(val *types.Value) *types.Value {
-	switch val.Type() {
-	case types.TypeArray:
-		return types.NewBool(true)
-
-	case types.TypeObject:
-		// Objects that implement Traversable are iterable
-		// For now, we'll just check if it's an object
-		// TODO: Implement proper interface checking
-		return types.NewBool(true)
-
-	default:
-		return types.NewBool(false)
-	}
-}
-
-// IsCountable checks if a variable can be counted
-// is_countable(mixed $value): bool
-func IsCountable(val *types.Value) *types.Value {
-	switch val.Type() {
-	case types.TypeArray:
-		return types.NewBool(true)
-
-	case types.TypeObject:
-		// Objects that implement Countable are countable
-		// TODO: Implement proper interface checking
-		obj := val.ToObject()
-		_, hasCount := obj.ClassEntry.Methods["count"]
-		return types.NewBool(hasCount)
-
-	default:
-		return types.NewBool(false)
-	}
-}
-
-// GetType returns the type of a variable
-// gettype(mixed $value): string
-func GetType(val *types.Value) *types.Value {
-	switch val.Type() {
-	case types.TypeNull:
-		return types.NewString("NULL")
-	case types.TypeBool:
-		return types.NewString("boolean")
-	case types.TypeInt:
-		return types.NewString("integer")
-	case types.TypeFloat:
-		return types.NewString("double")
-	case types.TypeString:
-		return types.NewString("string")
-	case types.TypeArray:
-		return types.NewString("array")
-	case types.TypeObject:
-		return types.NewString("object")
-	case types.TypeResource:
-		return types.NewString("resource")
-	default:
-		return types.NewString("unknown type")
+		result.Set(types.NewInt(int64(id)), types.NewResource(res))
 	}
+	return types.NewArray(result)
 }
@@ -1,6 +1,8 @@
 package varfuncs
 
 import (
+	"fmt"
+	"math"
 	"strings"
 	"testing"
 
@@ -166,298 +168,228 @@ func TestVarExport_WithReturn(t *testing.T) {
 }
 
 // ============================================================================
-// Type Checking Tests
+// Resource Tests
 // ============================================================================
 
-func TestIsNull(t *testing.T) {
+func TestIsResource(t *testing.T) {
+	// Create a test resource
+	res := types.NewResourceHandle("test", "test data")
+
 	tests := []struct {
 		value    *types.Value
 		expected bool
 	}{
-		{types.NewNull(), true},
-		{types.NewBool(false), false},
+		{types.NewResource(res), true},
+		{types.NewString("resource"), false},
 		{types.NewInt(0), false},
-		{types.NewString(""), false},
 	}
 
 	for _, tt := range tests {
-		result := IsNull(tt.value)
+		result := IsResource(tt.value)
 		if result.ToBool() != tt.expected {
-			t.Errorf("IsNull(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
+			t.Errorf("IsResource(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
 		}
 	}
-}
-
-func TestIsBool(t *testing.T) {
-	tests := []struct {
-		value    *types.Value
-		expected bool
-	}{
-		{types.NewBool(true), true},
-		{types.NewBool(false), true},
-		{types.NewInt(1), false},
-		{types.NewString("true"), false},
-	}
 
-	for _, tt := range tests {
-		result := IsBool(tt.value)
-		if result.ToBool() != tt.expected {
-			t.Errorf("IsBool(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
-		}
+	// Test closed resource
+	res.Close()
+	result := IsResource(types.NewResource(res))
+	if result.ToBool() {
+		t.Errorf("IsResource(closed resource) should return false")
 	}
 }
 
-func TestIsInt(t *testing.T) {
-	tests := []struct {
-		value    *types.Value
-		expected bool
-	}{
-		{types.NewInt(42), true},
-		{types.NewInt(0), true},
-		{types.NewInt(-100), true},
-		{types.NewFloat(42.0), false},
-		{types.NewString("42"), false},
-	}
+func TestResourceIntrospection(t *testing.T) {
+	res := types.NewResourceHandle("stream", nil)
+	value := types.NewResource(res)
 
-	for _, tt := range tests {
-		result := IsInt(tt.value)
-		if result.ToBool() != tt.expected {
-			t.Errorf("IsInt(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
-		}
+	if got := GetResourceType(value).ToString(); got != "stream" {
+		t.Errorf("GetResourceType() = %q, want \"stream\"", got)
 	}
-}
-
-func TestIsFloat(t *testing.T) {
-	tests := []struct {
-		value    *types.Value
-		expected bool
-	}{
-		{types.NewFloat(3.14), true},
-		{types.NewFloat(0.0), true},
-		{types.NewInt(42), false},
-		{types.NewString("3.14"), false},
+	if got := GetResourceID(value).ToInt(); got != int64(res.ID()) {
+		t.Errorf("GetResourceID() = %d, want %d", got, res.ID())
 	}
-
-	for _, tt := range tests {
-		result := IsFloat(tt.value)
-		if result.ToBool() != tt.expected {
-			t.Errorf("IsFloat(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
-		}
+	if !GetResources(types.NewString("stream")).ToArray().HasKey(types.NewInt(int64(res.ID()))) {
+		t.Error("GetResources(\"stream\") should include the open stream")
 	}
-}
 
-func TestIsString(t *testing.T) {
-	tests := []struct {
-		value    *types.Value
-		expected bool
-	}{
-		{types.NewString("hello"), true},
-		{types.NewString(""), true},
-		{types.NewInt(42), false},
-		{types.NewBool(false), false},
+	res.Close()
+	if got := GetResourceType(value).ToString(); got != "Unknown" {
+		t.Errorf("GetResourceType(closed) = %q, want \"Unknown\"", got)
 	}
-
-	for _, tt := range tests {
-		result := IsString(tt.value)
-		if result.ToBool() != tt.expected {
-			t.Errorf("IsString(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
-		}
+	if got, _ := NewDumper(nil).VarDump(value); got != fmt.Sprintf("resource(%d) of type (Unknown)\n", res.ID()) {
+		t.Errorf("VarDump(closed) = %q", got)
 	}
 }
 
-func TestIsArray(t *testing.T) {
-	arr := types.NewEmptyArray()
-
-	tests := []struct {
-		value    *types.Value
-		expected bool
-	}{
-		{types.NewArray(arr), true},
-		{types.NewString("array"), false},
-		{types.NewInt(0), false},
-	}
-
-	for _, tt := range tests {
-		result := IsArray(tt.value)
-		if result.ToBool() != tt.expected {
-			t.Errorf("IsArray(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
-		}
-	}
-}
+// ============================================================================
+// Object Dump Tests
+// ============================================================================
 
-func TestIsObject(t *testing.T) {
-	// Create a simple class for testing
-	class := types.NewClassEntry("TestClass")
+func TestDumper_ObjectRecursion(t *testing.T) {
+	class := types.NewClassEntry("Node")
 	obj := types.NewObjectFromClass(class)
+	obj.SetProperty("self", types.NewObject(obj), nil)
 
-	tests := []struct {
-		value    *types.Value
-		expected bool
-	}{
-		{types.NewObject(obj), true},
-		{types.NewString("object"), false},
-		{types.NewInt(0), false},
+	output, err := (&Dumper{}).VarDump(types.NewObject(obj))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-
-	for _, tt := range tests {
-		result := IsObject(tt.value)
-		if result.ToBool() != tt.expected {
-			t.Errorf("IsObject(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
-		}
+	if !strings.Contains(output, "[\"self\"]=>\n  *RECURSION*\n") {
+		t.Errorf("Expected recursion marker, got:\n%s", output)
 	}
 }
 
-func TestIsResource(t *testing.T) {
-	// Create a test resource
-	res := types.NewResourceHandle("test", "test data")
-
-	tests := []struct {
-		value    *types.Value
-		expected bool
-	}{
-		{types.NewResource(res), true},
-		{types.NewString("resource"), false},
-		{types.NewInt(0), false},
-	}
-
-	for _, tt := range tests {
-		result := IsResource(tt.value)
-		if result.ToBool() != tt.expected {
-			t.Errorf("IsResource(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
-		}
-	}
+func TestDumper_SharedObjectIsNotRecursion(t *testing.T) {
+	shared := types.NewObjectFromClass(types.NewClassEntry("Leaf"))
+	arr := types.NewEmptyArray()
+	arr.Append(types.NewObject(shared))
+	arr.Append(types.NewObject(shared))
 
-	// Test closed resource
-	res.Close()
-	result := IsResource(types.NewResource(res))
-	if result.ToBool() {
-		t.Errorf("IsResource(closed resource) should return false")
+	output, _ := (&Dumper{}).VarDump(types.NewArray(arr))
+	if strings.Contains(output, "RECURSION") {
+		t.Errorf("Repeated object should be dumped in full, got:\n%s", output)
 	}
 }
 
-func TestIsNumeric(t *testing.T) {
-	tests := []struct {
-		value    *types.Value
-		expected bool
-	}{
-		{types.NewInt(42), true},
-		{types.NewFloat(3.14), true},
-		{types.NewString("42"), true},
-		{types.NewString("3.14"), true},
-		{types.NewString("hello"), false},
-		{types.NewBool(true), false},
+func TestDumper_PropertySource(t *testing.T) {
+	obj := types.NewObjectFromClass(types.NewClassEntry("Custom"))
+	source := func(o *types.Object) ([]types.DebugProperty, error) {
+		return []types.DebugProperty{
+			{Key: types.NewString("secret"), Value: types.NewString("x"), Visibility: types.VisibilityPrivate, Class: "Custom"},
+		}, nil
 	}
 
-	for _, tt := range tests {
-		result := IsNumeric(tt.value)
-		if result.ToBool() != tt.expected {
-			t.Errorf("IsNumeric(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
-		}
+	output, err := NewDumper(source).PrintR(types.NewObject(obj))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "[secret:Custom:private] => x") {
+		t.Errorf("Expected annotated private key, got:\n%s", output)
 	}
 }
 
-func TestIsScalar(t *testing.T) {
+// ============================================================================
+// Output Format Tests
+// ============================================================================
+
+// nestedTestArray builds [1, 'a' => [true, null], 'f' => 1.5]
+func nestedTestArray() *types.Value {
+	inner := types.NewEmptyArray()
+	inner.Append(types.NewBool(true))
+	inner.Append(types.NewNull())
 	arr := types.NewEmptyArray()
+	arr.Append(types.NewInt(1))
+	arr.Set(types.NewString("a"), types.NewArray(inner))
+	arr.Set(types.NewString("f"), types.NewFloat(1.5))
+	return types.NewArray(arr)
+}
 
-	tests := []struct {
-		value    *types.Value
-		expected bool
-	}{
-		{types.NewInt(42), true},
-		{types.NewFloat(3.14), true},
-		{types.NewString("hello"), true},
-		{types.NewBool(true), true},
-		{types.NewArray(arr), false},
-		{types.NewNull(), false},
+func TestDumper_VarDumpFormat(t *testing.T) {
+	output, err := (&Dumper{}).VarDump(nestedTestArray())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "array(3) {\n  [0]=>\n  int(1)\n  [\"a\"]=>\n  array(2) {\n    [0]=>\n    bool(true)\n    [1]=>\n    NULL\n  }\n  [\"f\"]=>\n  float(1.5)\n}\n"
+	if output != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
 	}
 
-	for _, tt := range tests {
-		result := IsScalar(tt.value)
-		if result.ToBool() != tt.expected {
-			t.Errorf("IsScalar(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
+	for _, tt := range []struct {
+		f        float64
+		expected string
+	}{
+		{1, "float(1)\n"},
+		{0.1, "float(0.1)\n"},
+		{1e100, "float(1.0E+100)\n"},
+	} {
+		if output, _ := (&Dumper{}).VarDump(types.NewFloat(tt.f)); output != tt.expected {
+			t.Errorf("VarDump(%v) = %q, want %q", tt.f, output, tt.expected)
 		}
 	}
 }
 
-func TestIsIterable(t *testing.T) {
-	arr := types.NewEmptyArray()
-	class := types.NewClassEntry("TestClass")
-	obj := types.NewObjectFromClass(class)
+func TestDumper_PrintRFormat(t *testing.T) {
+	output, err := (&Dumper{}).PrintR(nestedTestArray())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "Array\n(\n    [0] => 1\n    [a] => Array\n        (\n            [0] => 1\n            [1] => \n        )\n\n    [f] => 1.5\n)\n"
+	if output != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
+	}
 
-	tests := []struct {
-		value    *types.Value
-		expected bool
-	}{
-		{types.NewArray(arr), true},
-		{types.NewObject(obj), true},
-		{types.NewString("hello"), false},
-		{types.NewInt(42), false},
+	obj := types.NewObjectFromClass(types.NewClassEntry("Node"))
+	obj.SetProperty("self", types.NewObject(obj), nil)
+	output, _ = (&Dumper{}).PrintR(types.NewObject(obj))
+	expected = "Node Object\n(\n    [self] => Node Object\n *RECURSION*\n)\n"
+	if output != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
 	}
 
-	for _, tt := range tests {
-		result := IsIterable(tt.value)
-		if result.ToBool() != tt.expected {
-			t.Errorf("IsIterable(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
+	for _, tt := range []struct {
+		val      *types.Value
+		expected string
+	}{
+		{types.NewBool(false), ""},
+		{types.NewNull(), ""},
+		{types.NewFloat(0.1), "0.1"},
+		{types.NewFloat(1e15), "1.0E+15"},
+	} {
+		if output, _ := (&Dumper{}).PrintR(tt.val); output != tt.expected {
+			t.Errorf("PrintR(%v) = %q, want %q", tt.val, output, tt.expected)
 		}
 	}
 }
 
-func TestGetType(t *testing.T) {
-	arr := types.NewEmptyArray()
-	class := types.NewClassEntry("TestClass")
-	obj := types.NewObjectFromClass(class)
-	res := types.NewResourceHandle("test", "data")
+func TestDumper_VarExportFormat(t *testing.T) {
+	expected := "array (\n  0 => 1,\n  'a' => \n  array (\n    0 => true,\n    1 => NULL,\n  ),\n  'f' => 1.5,\n)"
+	if output := (&Dumper{}).VarExport(nestedTestArray()); output != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
+	}
 
-	tests := []struct {
-		value    *types.Value
+	for _, tt := range []struct {
+		val      *types.Value
 		expected string
 	}{
 		{types.NewNull(), "NULL"},
-		{types.NewBool(true), "boolean"},
-		{types.NewInt(42), "integer"},
-		{types.NewFloat(3.14), "double"},
-		{types.NewString("hello"), "string"},
-		{types.NewArray(arr), "array"},
-		{types.NewObject(obj), "object"},
-		{types.NewResource(res), "resource"},
-	}
-
-	for _, tt := range tests {
-		result := GetType(tt.value)
-		if result.ToString() != tt.expected {
-			t.Errorf("GetType(%v) = %v, want %v", tt.value, result.ToString(), tt.expected)
+		{types.NewBool(false), "false"},
+		{types.NewInt(math.MinInt64), "-9223372036854775807-1"},
+		{types.NewFloat(1), "1.0"},
+		{types.NewFloat(0.1), "0.1"},
+		{types.NewFloat(1e25), "1.0E+25"},
+		{types.NewString("it's a \\ test"), `'it\'s a \\ test'`},
+		{types.NewString("a\x00b"), `'a' . "\0" . 'b'`},
+	} {
+		if output := (&Dumper{}).VarExport(tt.val); output != tt.expected {
+			t.Errorf("VarExport(%v) = %q, want %q", tt.val, output, tt.expected)
 		}
 	}
 }
 
-// ============================================================================
-// Alias Tests
-// ============================================================================
+func TestDumper_VarExportObjects(t *testing.T) {
+	point := types.NewObjectFromClass(types.NewClassEntry("Point"))
+	point.SetProperty("x", types.NewInt(1), nil)
+	std := types.NewObjectFromClass(types.NewClassEntry("stdClass"))
+	std.SetProperty("p", types.NewObject(point), nil)
 
-func TestIsLong(t *testing.T) {
-	result := IsLong(types.NewInt(42))
-	if !result.ToBool() {
-		t.Errorf("IsLong(42) should return true")
+	expected := "(object) array(\n   'p' => \n  \\Point::__set_state(array(\n     'x' => 1,\n  )),\n)"
+	if output := (&Dumper{}).VarExport(types.NewObject(std)); output != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
 	}
 }
 
-func TestIsInteger(t *testing.T) {
-	result := IsInteger(types.NewInt(42))
-	if !result.ToBool() {
-		t.Errorf("IsInteger(42) should return true")
-	}
-}
+func TestDumper_VarExportRecursion(t *testing.T) {
+	obj := types.NewObjectFromClass(types.NewClassEntry("stdClass"))
+	obj.SetProperty("self", types.NewObject(obj), nil)
 
-func TestIsDouble(t *testing.T) {
-	result := IsDouble(types.NewFloat(3.14))
-	if !result.ToBool() {
-		t.Errorf("IsDouble(3.14) should return true")
+	var warnings []string
+	d := &Dumper{Warning: func(message string) { warnings = append(warnings, message) }}
+	expected := "(object) array(\n   'self' => NULL,\n)"
+	if output := d.VarExport(types.NewObject(obj)); output != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
 	}
-}
-
-func TestIsReal(t *testing.T) {
-	result := IsReal(types.NewFloat(3.14))
-	if !result.ToBool() {
-		t.Errorf("IsReal(3.14) should return true")
+	if len(warnings) != 1 || warnings[0] != "var_export does not handle circular references" {
+		t.Errorf("Expected circular reference warning, got %v", warnings)
 	}
 }
@@ -0,0 +1,465 @@
+package varfuncs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// serialize() Format
+// ============================================================================
+
+// Serializer converts values to and from the serialize() format. Its hooks
+// are optional: without them objects are written with their raw
+// properties and read back as objects of the named class holding them.
+type Serializer struct {
+	// Properties returns the entries written for an object, such as the
+	// result of __serialize() or the properties named by __sleep()
+	Properties func(obj *types.Object) (*types.Array, error)
+	// Instantiate creates an object of a class from the entries read
+	Instantiate func(class string, data *types.Array) (*types.Value, error)
+}
+
+// UnserializeError is the position at which malformed data was found
+type UnserializeError struct {
+	Offset int // Byte offset of the value that could not be read
+	Length int // Length of the data
+}
+
+// Error describes the position, as unserialize()'s notice does
+func (e *UnserializeError) Error() string {
+	return fmt.Sprintf("Error at offset %d of %d bytes", e.Offset, e.Length)
+}
+
+// Serialize returns the serialize() representation of a value
+func Serialize(val *types.Value) *types.Value {
+	out, err := (&Serializer{}).Serialize(val)
+	if err != nil {
+		return types.NewBool(false)
+	}
+	return types.NewString(out)
+}
+
+// Unserialize reads a value from its serialize() representation; false if
+// the data is malformed
+func Unserialize(data string) *types.Value {
+	val, err := (&Serializer{}).Unserialize(data)
+	if err != nil {
+		return types.NewBool(false)
+	}
+	return val
+}
+
+// Serialize returns the serialize() representation of a value
+func (s *Serializer) Serialize(val *types.Value) (string, error) {
+	return s.NewEncoder().Encode(val)
+}
+
+// Unserialize reads a value from its serialize() representation. Data
+// following the value is ignored.
+func (s *Serializer) Unserialize(data string) (*types.Value, error) {
+	val, _, err := s.NewDecoder(data).Decode(0)
+	return val, err
+}
+
+// ObjectProperties returns the raw properties of an object as serialize()
+// writes them: private property names are prefixed with "\0Class\0" and
+// protected ones with "\0*\0". Uninitialized typed properties are skipped.
+func ObjectProperties(obj *types.Object) *types.Array {
+	props := types.NewEmptyArray()
+	for _, prop := range obj.DebugProperties() {
+		name := prop.Key.ToString()
+		if p := obj.Properties[propertyKey(obj, prop.Class, name)]; p.Value == nil && p.Type != "" {
+			continue
+		}
+		switch prop.Visibility {
+		case types.VisibilityPrivate:
+			name = "\x00" + prop.Class + "\x00" + name
+		case types.VisibilityProtected:
+			name = "\x00*\x00" + name
+		}
+		props.Set(types.NewString(name), prop.Value)
+	}
+	return props
+}
+
+// RestoreProperties sets the entries read for an object as its
+// properties, undoing the name prefixes of ObjectProperties
+func RestoreProperties(obj *types.Object, data *types.Array) {
+	data.Each(func(key, value *types.Value) bool {
+		name, visibility := key.ToString(), types.VisibilityPublic
+		if strings.HasPrefix(name, "\x00") {
+			if class, prop, ok := strings.Cut(name[1:], "\x00"); ok {
+				name, visibility = prop, types.VisibilityPrivate
+				if class == "*" {
+					visibility = types.VisibilityProtected
+				} else {
+					name = propertyKey(obj, class, name)
+				}
+			}
+		}
+		if prop, ok := obj.Properties[name]; ok {
+			prop.Value = value
+			return true
+		}
+		obj.AddProperty(name, &types.Property{Value: value, Visibility: visibility})
+		return true
+	})
+}
+
+// propertyKey returns the key of a property declared by class in an
+// object: a private property shadowed in a subclass has a key of its own
+func propertyKey(obj *types.Object, class, name string) string {
+	if key := types.PrivatePropertyKey(class, name); obj.Properties[key] != nil {
+		return key
+	}
+	return name
+}
+
+// ============================================================================
+// Encoding
+// ============================================================================
+
+// Encoder writes values in the serialize() format. Values written by the
+// same encoder share the numbering of back references, as the variables
+// of a session do.
+type Encoder struct {
+	s       *Serializer
+	n       int                   // Number of values written
+	objects map[*types.Object]int // Number of each object written
+}
+
+// NewEncoder returns an encoder using the serializer's hooks
+func (s *Serializer) NewEncoder() *Encoder {
+	return &Encoder{s: s, objects: make(map[*types.Object]int)}
+}
+
+// Encode returns the representation of a value. An object written before
+// is written as a back reference (r:N;) to it.
+func (e *Encoder) Encode(val *types.Value) (string, error) {
+	var out strings.Builder
+	if err := e.encode(&out, val); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func (e *Encoder) encode(out *strings.Builder, val *types.Value) error {
+	val = val.Deref()
+	e.n++
+	switch {
+	case val.IsNull() || val.IsUndef():
+		out.WriteString("N;")
+	case val.IsBool():
+		if val.ToBool() {
+			out.WriteString("b:1;")
+		} else {
+			out.WriteString("b:0;")
+		}
+	case val.IsInt():
+		fmt.Fprintf(out, "i:%d;", val.ToInt())
+	case val.IsFloat():
+		fmt.Fprintf(out, "d:%s;", types.FormatFloat(val.ToFloat(), types.SerializePrecision()))
+	case val.IsString():
+		s := val.ToString()
+		fmt.Fprintf(out, "s:%d:\"%s\";", len(s), s)
+	case val.IsArray():
+		arr := val.ToArray()
+		fmt.Fprintf(out, "a:%d:{", arr.Len())
+		if err := e.entries(out, arr); err != nil {
+			return err
+		}
+		out.WriteByte('}')
+	case val.IsObject():
+		obj := val.ToObject()
+		if n, ok := e.objects[obj]; ok {
+			fmt.Fprintf(out, "r:%d;", n)
+			return nil
+		}
+		e.objects[obj] = e.n
+
+		props := ObjectProperties(obj)
+		if e.s.Properties != nil {
+			var err error
+			if props, err = e.s.Properties(obj); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(out, "O:%d:\"%s\":%d:{", len(obj.ClassName), obj.ClassName, props.Len())
+		if err := e.entries(out, props); err != nil {
+			return err
+		}
+		out.WriteByte('}')
+	default:
+		// Resources and other values cannot be represented
+		out.WriteString("i:0;")
+	}
+	return nil
+}
+
+// entries writes the keys and values of an array or object
+func (e *Encoder) entries(out *strings.Builder, arr *types.Array) error {
+	var err error
+	arr.Each(func(key, value *types.Value) bool {
+		if key.IsInt() {
+			fmt.Fprintf(out, "i:%d;", key.ToInt())
+		} else {
+			k := key.ToString()
+			fmt.Fprintf(out, "s:%d:\"%s\";", len(k), k)
+		}
+		err = e.encode(out, value)
+		return err == nil
+	})
+	return err
+}
+
+// ============================================================================
+// Decoding
+// ============================================================================
+
+// Decoder reads values in the serialize() format from a string. Values
+// read by the same decoder share the numbering of back references.
+type Decoder struct {
+	s      *Serializer
+	data   string
+	pos    int
+	values []*types.Value // Values read, for r:N; and R:N; (numbered from 1)
+}
+
+// NewDecoder returns a decoder of data using the serializer's hooks
+func (s *Serializer) NewDecoder(data string) *Decoder {
+	return &Decoder{s: s, data: data}
+}
+
+// Decode reads the value at an offset of the data and returns it with
+// the offset following it
+func (d *Decoder) Decode(offset int) (*types.Value, int, error) {
+	d.pos = offset
+	val, err := d.value()
+	if err != nil {
+		return nil, offset, err
+	}
+	return val, d.pos, nil
+}
+
+// fail reports malformed data at an offset
+func (d *Decoder) fail(offset int) error {
+	return &UnserializeError{Offset: offset, Length: len(d.data)}
+}
+
+func (d *Decoder) value() (*types.Value, error) {
+	start := d.pos
+	if d.pos+1 >= len(d.data) {
+		return nil, d.fail(start)
+	}
+	kind := d.data[d.pos]
+	if kind == 'N' {
+		if d.data[d.pos+1] != ';' {
+			return nil, d.fail(start)
+		}
+		d.pos += 2
+		return d.push(types.NewNull()), nil
+	}
+	if d.data[d.pos+1] != ':' {
+		return nil, d.fail(start)
+	}
+	d.pos += 2
+
+	switch kind {
+	case 'b':
+		n, ok := d.integer(';')
+		if !ok || (n != 0 && n != 1) {
+			return nil, d.fail(start)
+		}
+		return d.push(types.NewBool(n == 1)), nil
+
+	case 'i':
+		n, ok := d.integer(';')
+		if !ok {
+			return nil, d.fail(start)
+		}
+		return d.push(types.NewInt(n)), nil
+
+	case 'd':
+		end := strings.IndexByte(d.data[d.pos:], ';')
+		if end < 0 {
+			return nil, d.fail(start)
+		}
+		f, ok := parseSerializedFloat(d.data[d.pos : d.pos+end])
+		if !ok {
+			return nil, d.fail(start)
+		}
+		d.pos += end + 1
+		return d.push(types.NewFloat(f)), nil
+
+	case 's':
+		s, ok := d.str()
+		if !ok || !d.expect(';') {
+			return nil, d.fail(start)
+		}
+		return d.push(types.NewString(s)), nil
+
+	case 'a':
+		n, ok := d.integer(':')
+		if !ok || n < 0 || !d.expect('{') {
+			return nil, d.fail(start)
+		}
+		arr := types.NewEmptyArray()
+		val := d.push(types.NewArray(arr))
+		if err := d.entries(arr, n); err != nil {
+			return nil, err
+		}
+		return val, nil
+
+	case 'O':
+		class, ok := d.str()
+		if !ok || !d.expect(':') {
+			return nil, d.fail(start)
+		}
+		n, ok := d.integer(':')
+		if !ok || n < 0 || !d.expect('{') {
+			return nil, d.fail(start)
+		}
+		// The object is numbered before its properties, which may refer to it
+		slot := len(d.values)
+		d.values = append(d.values, nil)
+		props := types.NewEmptyArray()
+		if err := d.entries(props, n); err != nil {
+			return nil, err
+		}
+		obj, err := d.instantiate(class, props)
+		if err != nil {
+			return nil, err
+		}
+		d.values[slot] = obj
+		return obj, nil
+
+	case 'r', 'R':
+		n, ok := d.integer(';')
+		if !ok || n < 1 || int(n) > len(d.values) || d.values[n-1] == nil {
+			return nil, d.fail(start)
+		}
+		val := d.values[n-1]
+		if kind == 'r' {
+			d.push(val)
+		}
+		return val, nil
+	}
+	return nil, d.fail(start)
+}
+
+// entries reads the n keys and values of an array or object, up to its
+// closing brace
+func (d *Decoder) entries(arr *types.Array, n int64) error {
+	for i := int64(0); i < n; i++ {
+		start := d.pos
+		if d.pos+1 >= len(d.data) || d.data[d.pos+1] != ':' {
+			return d.fail(start)
+		}
+		var key *types.Value
+		switch d.data[d.pos] {
+		case 'i':
+			d.pos += 2
+			k, ok := d.integer(';')
+			if !ok {
+				return d.fail(start)
+			}
+			key = types.NewInt(k)
+		case 's':
+			d.pos += 2
+			k, ok := d.str()
+			if !ok || !d.expect(';') {
+				return d.fail(start)
+			}
+			key = types.NewString(k)
+		default:
+			return d.fail(start)
+		}
+		val, err := d.value()
+		if err != nil {
+			return err
+		}
+		arr.Set(key, val)
+	}
+	if !d.expect('}') {
+		return d.fail(d.pos)
+	}
+	return nil
+}
+
+// instantiate creates the object of a class read with its entries
+func (d *Decoder) instantiate(class string, props *types.Array) (*types.Value, error) {
+	if d.s.Instantiate != nil {
+		return d.s.Instantiate(class, props)
+	}
+	obj := types.NewObjectInstance(class)
+	RestoreProperties(obj, props)
+	return types.NewObject(obj), nil
+}
+
+// push numbers a value read, for back references
+func (d *Decoder) push(val *types.Value) *types.Value {
+	d.values = append(d.values, val)
+	return val
+}
+
+// integer reads a decimal integer up to a terminator
+func (d *Decoder) integer(terminator byte) (int64, bool) {
+	end := strings.IndexByte(d.data[d.pos:], terminator)
+	if end <= 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(d.data[d.pos:d.pos+end], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	d.pos += end + 1
+	return n, true
+}
+
+// str reads a length-prefixed quoted string: N:"..."
+func (d *Decoder) str() (string, bool) {
+	n, ok := d.integer(':')
+	if !ok || n < 0 || !d.expect('"') {
+		return "", false
+	}
+	end := d.pos + int(n)
+	if end+1 > len(d.data) || d.data[end] != '"' {
+		return "", false
+	}
+	s := d.data[d.pos:end]
+	d.pos = end + 1
+	return s, true
+}
+
+// expect consumes a byte, reporting whether it was there
+func (d *Decoder) expect(c byte) bool {
+	if d.pos < len(d.data) && d.data[d.pos] == c {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+// parseSerializedFloat parses the float of a d: value, including the INF
+// and NAN spellings
+func parseSerializedFloat(s string) (float64, bool) {
+	switch s {
+	case "INF":
+		return math.Inf(1), true
+	case "-INF":
+		return math.Inf(-1), true
+	case "NAN":
+		return math.NaN(), true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return 0, false
+	}
+	return f, true
+}
@@ -0,0 +1,93 @@
+package varfuncs
+
+import (
+	"math"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestSerializeScalarsAndArrays(t *testing.T) {
+	list := types.NewEmptyArray()
+	list.Append(types.NewInt(1))
+	list.Set(types.NewString("k"), types.NewString("é\"x"))
+	list.Set(types.NewString("n"), types.NewNull())
+
+	tests := []struct {
+		val  *types.Value
+		want string
+	}{
+		{types.NewNull(), "N;"},
+		{types.NewBool(true), "b:1;"},
+		{types.NewInt(-7), "i:-7;"},
+		{types.NewFloat(0.1), "d:0.1;"},
+		{types.NewFloat(1), "d:1;"},
+		{types.NewFloat(1e100), "d:1.0E+100;"},
+		{types.NewFloat(math.Inf(-1)), "d:-INF;"},
+		{types.NewString("abc"), `s:3:"abc";`},
+		{types.NewArray(list), `a:3:{i:0;i:1;s:1:"k";s:4:"é"x";s:1:"n";N;}`},
+	}
+	for _, tt := range tests {
+		if got := Serialize(tt.val).ToString(); got != tt.want {
+			t.Errorf("Serialize(%v) = %q, want %q", tt.val, got, tt.want)
+			continue
+		}
+		back := Unserialize(tt.want)
+		if again := Serialize(back).ToString(); again != tt.want {
+			t.Errorf("Unserialize(%q) round trips to %q", tt.want, again)
+		}
+	}
+}
+
+func TestSerializeObjects(t *testing.T) {
+	obj := types.NewObjectInstance("Point")
+	obj.Properties["x"] = &types.Property{Value: types.NewInt(1), Visibility: types.VisibilityPublic}
+	obj.Properties["y"] = &types.Property{Value: types.NewInt(2), Visibility: types.VisibilityProtected}
+	obj.Properties["z"] = &types.Property{Value: types.NewInt(3), Visibility: types.VisibilityPrivate}
+
+	pair := types.NewEmptyArray()
+	pair.Append(types.NewObject(obj))
+	pair.Append(types.NewObject(obj))
+
+	want := "a:2:{i:0;O:5:\"Point\":3:{s:1:\"x\";i:1;s:4:\"\x00*\x00y\";i:2;s:8:\"\x00Point\x00z\";i:3;}i:1;r:2;}"
+	if got := Serialize(types.NewArray(pair)).ToString(); got != want {
+		t.Fatalf("Serialize = %q, want %q", got, want)
+	}
+
+	back := Unserialize(want).ToArray()
+	first, _ := back.Get(types.NewInt(0))
+	second, _ := back.Get(types.NewInt(1))
+	if first.ToObject() != second.ToObject() {
+		t.Errorf("back references should give the same object")
+	}
+	restored := first.ToObject()
+	if restored.ClassName != "Point" || restored.Properties["z"].Visibility != types.VisibilityPrivate ||
+		restored.Properties["y"].Visibility != types.VisibilityProtected || restored.Properties["x"].Value.ToInt() != 1 {
+		t.Errorf("restored object = %+v", restored.Properties)
+	}
+}
+
+func TestUnserializeMalformed(t *testing.T) {
+	tests := []struct {
+		data   string
+		offset int
+	}{
+		{"", 0},
+		{"i:12", 0},
+		{"b:2;", 0},
+		{`s:5:"abc";`, 0},
+		{"a:1:{i:0;x:1;}", 9},
+		{"a:1:{i:0;i:1;", 13},
+		{"r:1;", 0},
+	}
+	for _, tt := range tests {
+		_, err := (&Serializer{}).Unserialize(tt.data)
+		e, ok := err.(*UnserializeError)
+		if !ok || e.Offset != tt.offset || e.Length != len(tt.data) {
+			t.Errorf("Unserialize(%q) error = %v, want offset %d", tt.data, err, tt.offset)
+		}
+	}
+	if result := Unserialize("x"); !result.IsBool() || result.ToBool() {
+		t.Errorf("Unserialize of malformed data = %v, want false", result)
+	}
+}
@@ -0,0 +1,322 @@
+package vartype
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	varfuncs "github.com/krizos/php-go/pkg/stdlib/var"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Type Conversion Functions
+// ============================================================================
+
+// CastKind is a target type of a conversion: the extended value of the
+// CAST instruction the cast operators compile to
+type CastKind uint32
+
+const (
+	CastInt    CastKind = 1 // (int)
+	CastBool   CastKind = 2 // (bool)
+	CastFloat  CastKind = 3 // (float)
+	CastString CastKind = 4 // (string)
+	CastArray  CastKind = 5 // (array)
+	CastObject CastKind = 6 // (object)
+	CastNull   CastKind = 7 // (unset)
+)
+
+// Error is an error thrown by a conversion, such as the Error for an
+// object that cannot be converted to string. Class names the PHP
+// exception.
+type Error struct {
+	Class   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Converter runs the conversions with the hooks they need from the
+// engine. The zero value converts plain values: objects are not
+// Stringable and warnings are discarded.
+type Converter struct {
+	Warning  func(message string)                            // Receives warnings (nil discards them)
+	Stringer func(object *types.Value) (string, bool, error) // Converts Stringable objects; false if not Stringable
+}
+
+func (c *Converter) warn(format string, args ...interface{}) {
+	if c.Warning != nil {
+		c.Warning(fmt.Sprintf(format, args...))
+	}
+}
+
+// Intval returns the integer value of a variable. Strings are converted
+// in base 10 like the (int) cast unless another base is given; base 0
+// detects it from a 0x, 0o, 0 or 0b prefix.
+// intval(mixed $value, int $base = 10): int
+func (c *Converter) Intval(val *types.Value, base ...*types.Value) *types.Value {
+	if len(base) > 0 && val.IsString() {
+		if b := base[0].ToInt(); b != 10 {
+			return types.NewInt(parseIntBase(val.ToString(), b))
+		}
+	}
+	return types.NewInt(c.toInt(val))
+}
+
+// Floatval returns the float value of a variable
+// floatval(mixed $value): float
+func (c *Converter) Floatval(val *types.Value) *types.Value {
+	switch val.Type() {
+	case types.TypeString:
+		number, _ := types.ParseNumeric(val.ToString())
+		return types.NewFloat(number.ToFloat())
+	case types.TypeObject:
+		c.warn("Object of class %s could not be converted to float", val.ToObject().ClassName)
+	}
+	return types.NewFloat(val.ToFloat())
+}
+
+// Boolval returns the boolean value of a variable
+// boolval(mixed $value): bool
+func Boolval(val *types.Value) *types.Value {
+	return types.NewBool(val.ToBool())
+}
+
+// Strval returns the string value of a variable. Objects must be
+// Stringable; arrays convert to "Array" with a warning.
+// strval(mixed $value): string
+func (c *Converter) Strval(val *types.Value) (*types.Value, error) {
+	switch val.Type() {
+	case types.TypeString:
+		return val, nil
+	case types.TypeArray:
+		c.warn("Array to string conversion")
+	case types.TypeObject:
+		if c.Stringer != nil {
+			s, ok, err := c.Stringer(val)
+			if err != nil || ok {
+				return types.NewString(s), err
+			}
+		}
+		return nil, &Error{Class: "Error", Message: fmt.Sprintf("Object of class %s could not be converted to string", val.ToObject().ClassName)}
+	}
+	return types.NewString(val.ToString()), nil
+}
+
+// Cast converts a value to a type like the cast operators
+func (c *Converter) Cast(val *types.Value, kind CastKind) (*types.Value, error) {
+	val = val.Deref()
+	switch kind {
+	case CastInt:
+		return c.Intval(val), nil
+	case CastBool:
+		return Boolval(val), nil
+	case CastFloat:
+		return c.Floatval(val), nil
+	case CastString:
+		return c.Strval(val)
+	case CastArray:
+		return toArray(val), nil
+	case CastObject:
+		return toObject(val), nil
+	case CastNull:
+		return types.NewNull(), nil
+	}
+	return nil, fmt.Errorf("unknown cast type: %d", kind)
+}
+
+// settypeKinds maps the type names settype() accepts to their casts
+var settypeKinds = map[string]CastKind{
+	"bool": CastBool, "boolean": CastBool,
+	"int": CastInt, "integer": CastInt,
+	"float": CastFloat, "double": CastFloat,
+	"string": CastString,
+	"array":  CastArray,
+	"object": CastObject,
+	"null":   CastNull,
+}
+
+// Settype converts a variable, passed by reference, to a type in place
+// settype(mixed &$var, string $type): bool
+func (c *Converter) Settype(variable, typ *types.Value) (*types.Value, error) {
+	name := strings.ToLower(typ.ToString())
+	kind, ok := settypeKinds[name]
+	if !ok {
+		if name == "resource" {
+			return nil, &Error{Class: "ValueError", Message: "Cannot convert to resource type"}
+		}
+		return nil, &Error{Class: "ValueError", Message: "settype(): Argument #2 ($type) must be a valid type"}
+	}
+	converted, err := c.Cast(variable, kind)
+	if err != nil {
+		return nil, err
+	}
+	variable.Assign(converted)
+	return types.NewBool(true), nil
+}
+
+// ============================================================================
+// Conversions
+// ============================================================================
+
+// toInt converts a value like the (int) cast: numeric strings, including
+// floats in exponent notation ("1e3"), are converted by value, saturating
+// when out of range, and floats are truncated, wrapping around when out
+// of range
+func (c *Converter) toInt(val *types.Value) int64 {
+	switch val.Type() {
+	case types.TypeString:
+		number, _ := types.ParseNumeric(val.ToString())
+		if number.IsInt() {
+			return number.ToInt()
+		}
+		return floatToIntCapped(number.ToFloat())
+	case types.TypeFloat:
+		return FloatToInt(val.ToFloat())
+	case types.TypeObject:
+		c.warn("Object of class %s could not be converted to int", val.ToObject().ClassName)
+	}
+	return val.ToInt()
+}
+
+// FloatToInt converts a float to an integer like PHP on 64-bit platforms:
+// NaN and infinities become 0 and values out of range wrap around modulo
+// 2^64
+func FloatToInt(f float64) int64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+	if f >= -(1<<63) && f < 1<<63 {
+		return int64(f)
+	}
+	mod := math.Mod(f, 1<<64)
+	if mod < 0 {
+		mod += 1 << 64
+	}
+	if mod >= 1<<63 {
+		mod -= 1 << 64
+	}
+	return int64(mod)
+}
+
+// floatToIntCapped converts the float value of a numeric string to an
+// integer, saturating at the integer limits
+func floatToIntCapped(f float64) int64 {
+	switch {
+	case math.IsNaN(f) || math.IsInf(f, 0):
+		return 0
+	case f >= 1<<63:
+		return math.MaxInt64
+	case f < -(1 << 63):
+		return math.MinInt64
+	}
+	return int64(f)
+}
+
+// parseIntBase parses the leading integer of a string in a base from 2
+// to 36 like strtol(), saturating at the integer limits. Base 0 detects
+// the base from the prefix; prefixes matching the base are skipped.
+func parseIntBase(s string, base int64) int64 {
+	s = strings.TrimLeft(s, " \t\n\r\v\f")
+	negative := false
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+
+	if len(s) > 1 && s[0] == '0' {
+		switch prefix := s[1] | 0x20; {
+		case prefix == 'x' && (base == 16 || base == 0):
+			s, base = s[2:], 16
+		case prefix == 'o' && (base == 8 || base == 0):
+			s, base = s[2:], 8
+		case prefix == 'b' && (base == 2 || base == 0):
+			s, base = s[2:], 2
+		case base == 0:
+			s, base = s[1:], 8
+		}
+	}
+	if base == 0 {
+		base = 10
+	}
+	if base < 2 || base > 36 {
+		return 0
+	}
+
+	var n uint64
+	overflow := false
+	for i := 0; i < len(s); i++ {
+		digit := int64(36)
+		switch ch := s[i]; {
+		case ch >= '0' && ch <= '9':
+			digit = int64(ch - '0')
+		case ch|0x20 >= 'a' && ch|0x20 <= 'z':
+			digit = int64(ch|0x20-'a') + 10
+		}
+		if digit >= base {
+			break
+		}
+		if n > (math.MaxUint64-uint64(digit))/uint64(base) {
+			overflow = true
+			continue
+		}
+		n = n*uint64(base) + uint64(digit)
+	}
+
+	switch {
+	case negative && (overflow || n > 1<<63):
+		return math.MinInt64
+	case negative:
+		return -int64(n)
+	case overflow || n > math.MaxInt64:
+		return math.MaxInt64
+	}
+	return int64(n)
+}
+
+// toArray converts a value like the (array) cast: null becomes an empty
+// array, objects the array of their properties (with the name prefixes
+// of private and protected ones) and other values a single element array
+func toArray(val *types.Value) *types.Value {
+	switch val.Type() {
+	case types.TypeArray:
+		return val
+	case types.TypeNull:
+		return types.NewArray(types.NewEmptyArray())
+	case types.TypeObject:
+		props := types.NewEmptyArray()
+		varfuncs.ObjectProperties(val.ToObject()).Each(func(key, value *types.Value) bool {
+			props.Set(key, value.Copy())
+			return true
+		})
+		return types.NewArray(props)
+	}
+	return types.NewArray(types.NewArrayFromSlice([]*types.Value{val}))
+}
+
+// toObject converts a value like the (object) cast: arrays become a
+// stdClass with their entries as properties, null an empty stdClass and
+// other values a stdClass with the value as its "scalar" property
+func toObject(val *types.Value) *types.Value {
+	if val.IsObject() {
+		return val
+	}
+	obj := types.NewObjectInstance("stdClass")
+	set := func(name string, value *types.Value) {
+		obj.AddProperty(name, &types.Property{Value: value.Copy(), Visibility: types.VisibilityPublic})
+	}
+	switch val.Type() {
+	case types.TypeNull:
+	case types.TypeArray:
+		val.ToArray().Each(func(key, value *types.Value) bool {
+			set(key.ToString(), value)
+			return true
+		})
+	default:
+		set("scalar", val)
+	}
+	return types.NewObject(obj)
+}
@@ -0,0 +1,165 @@
+package vartype
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestIntval(t *testing.T) {
+	c := &Converter{}
+	tests := []struct {
+		value    *types.Value
+		base     []*types.Value
+		expected int64
+	}{
+		{types.NewString("42"), nil, 42},
+		{types.NewString(" 12abc"), nil, 12},
+		{types.NewString("1e3"), nil, 1000},
+		{types.NewString("-1.9"), nil, -1},
+		{types.NewString("abc"), nil, 0},
+		{types.NewString("99999999999999999999"), nil, math.MaxInt64},
+		{types.NewString("-99999999999999999999"), nil, math.MinInt64},
+		{types.NewFloat(1e20), nil, 7766279631452241920},
+		{types.NewFloat(math.NaN()), nil, 0},
+		{types.NewFloat(-3.99), nil, -3},
+		{types.NewBool(true), nil, 1},
+		{types.NewString("0x1A"), []*types.Value{types.NewInt(16)}, 26},
+		{types.NewString("1A"), []*types.Value{types.NewInt(16)}, 26},
+		{types.NewString("0x1A"), []*types.Value{types.NewInt(0)}, 26},
+		{types.NewString("042"), []*types.Value{types.NewInt(0)}, 34},
+		{types.NewString("0b101"), []*types.Value{types.NewInt(0)}, 5},
+		{types.NewString("0o17"), []*types.Value{types.NewInt(8)}, 15},
+		{types.NewString("-zz"), []*types.Value{types.NewInt(36)}, -1295},
+		{types.NewString("12"), []*types.Value{types.NewInt(2)}, 1},
+		{types.NewInt(42), []*types.Value{types.NewInt(8)}, 42},
+	}
+
+	for _, tt := range tests {
+		if got := c.Intval(tt.value, tt.base...).ToInt(); got != tt.expected {
+			t.Errorf("Intval(%v, %v) = %d, want %d", tt.value, tt.base, got, tt.expected)
+		}
+	}
+}
+
+func TestFloatvalAndBoolval(t *testing.T) {
+	c := &Converter{}
+	floats := []struct {
+		value    *types.Value
+		expected float64
+	}{
+		{types.NewString("1.5e3abc"), 1500},
+		{types.NewString(" .5"), 0.5},
+		{types.NewString("inf"), 0},
+		{types.NewInt(3), 3},
+		{types.NewNull(), 0},
+	}
+	for _, tt := range floats {
+		if got := c.Floatval(tt.value).ToFloat(); got != tt.expected {
+			t.Errorf("Floatval(%v) = %v, want %v", tt.value, got, tt.expected)
+		}
+	}
+
+	bools := []struct {
+		value    *types.Value
+		expected bool
+	}{
+		{types.NewString("0"), false},
+		{types.NewString("0.0"), true},
+		{types.NewArray(types.NewEmptyArray()), false},
+		{types.NewFloat(math.NaN()), true},
+	}
+	for _, tt := range bools {
+		if got := Boolval(tt.value).ToBool(); got != tt.expected {
+			t.Errorf("Boolval(%v) = %v, want %v", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestStrval(t *testing.T) {
+	var warnings []string
+	c := &Converter{
+		Warning: func(message string) { warnings = append(warnings, message) },
+		Stringer: func(object *types.Value) (string, bool, error) {
+			return "stringable", object.ToObject().ClassName == "Name", nil
+		},
+	}
+
+	if got, _ := c.Strval(types.NewInt(-7)); got.ToString() != "-7" {
+		t.Errorf("Strval(-7) = %v", got)
+	}
+	if got, _ := c.Strval(types.NewArray(types.NewEmptyArray())); got.ToString() != "Array" || len(warnings) != 1 || warnings[0] != "Array to string conversion" {
+		t.Errorf("Strval([]) = %v with warnings %v", got, warnings)
+	}
+	name := types.NewObject(types.NewObjectInstance("Name"))
+	if got, err := c.Strval(name); err != nil || got.ToString() != "stringable" {
+		t.Errorf("Strval(Name) = %v (%v)", got, err)
+	}
+	_, err := c.Strval(types.NewObject(types.NewObjectInstance("Plain")))
+	if e, ok := err.(*Error); !ok || e.Class != "Error" || e.Message != "Object of class Plain could not be converted to string" {
+		t.Errorf("Expected an Error for a non-Stringable object, got %v", err)
+	}
+}
+
+func TestCast_ArrayAndObject(t *testing.T) {
+	c := &Converter{}
+
+	class := types.NewClassEntry("Point")
+	class.Properties["x"] = &types.PropertyDef{Name: "x", Visibility: types.VisibilityPublic, DeclaringClass: "Point", Default: types.NewInt(1)}
+	class.Properties["y"] = &types.PropertyDef{Name: "y", Visibility: types.VisibilityPrivate, DeclaringClass: "Point", Default: types.NewInt(2)}
+	arr, _ := c.Cast(types.NewObject(types.NewObjectFromClass(class)), CastArray)
+	var keys []string
+	arr.ToArray().Each(func(key, value *types.Value) bool {
+		keys = append(keys, strings.ReplaceAll(key.ToString(), "\x00", "\\0"))
+		return true
+	})
+	if strings.Join(keys, ",") != "x,\\0Point\\0y" {
+		t.Errorf("(array) object keys = %v", keys)
+	}
+	if scalar, _ := c.Cast(types.NewString("a"), CastArray); scalar.ToArray().Len() != 1 {
+		t.Errorf("(array) \"a\" = %v", scalar)
+	}
+	if empty, _ := c.Cast(types.NewNull(), CastArray); empty.ToArray().Len() != 0 {
+		t.Errorf("(array) null = %v", empty)
+	}
+
+	entries := types.NewEmptyArray()
+	entries.Set(types.NewString("a"), types.NewInt(1))
+	entries.Set(types.NewInt(0), types.NewInt(2))
+	obj, _ := c.Cast(types.NewArray(entries), CastObject)
+	if obj.ToObject().ClassName != "stdClass" || obj.ToObject().Properties["a"] == nil || obj.ToObject().Properties["0"] == nil {
+		t.Errorf("(object) array = %v", obj.ToObject().Properties)
+	}
+	scalar, _ := c.Cast(types.NewInt(5), CastObject)
+	if prop := scalar.ToObject().Properties["scalar"]; prop == nil || prop.Value.ToInt() != 5 {
+		t.Errorf("(object) 5 = %v", scalar.ToObject().Properties)
+	}
+}
+
+func TestSettype(t *testing.T) {
+	c := &Converter{}
+	variable := types.NewReference(types.NewString("12abc"))
+
+	if result, err := c.Settype(variable, types.NewString("INTEGER")); err != nil || !result.ToBool() {
+		t.Fatalf("Settype() = %v (%v)", result, err)
+	}
+	if got := variable.Deref(); !got.IsInt() || got.ToInt() != 12 {
+		t.Errorf("Expected int(12), got %v", got)
+	}
+	c.Settype(variable, types.NewString("array"))
+	if got := variable.Deref(); !got.IsArray() || got.ToArray().Len() != 1 {
+		t.Errorf("Expected [12], got %v", got)
+	}
+
+	for typ, message := range map[string]string{
+		"resource": "Cannot convert to resource type",
+		"number":   "settype(): Argument #2 ($type) must be a valid type",
+	} {
+		_, err := c.Settype(variable, types.NewString(typ))
+		if e, ok := err.(*Error); !ok || e.Class != "ValueError" || e.Message != message {
+			t.Errorf("Settype(%s): expected ValueError %q, got %v", typ, message, err)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package vartype
+
+import (
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Type Checking Functions
+// ============================================================================
+
+// IsNull checks if a variable is null
+// is_null(mixed $value): bool
+func IsNull(val *types.Value) *types.Value {
+	return types.NewBool(val.Type() == types.TypeNull)
+}
+
+// IsBool checks if a variable is a boolean
+// is_bool(mixed $value): bool
+func IsBool(val *types.Value) *types.Value {
+	return types.NewBool(val.Type() == types.TypeBool)
+}
+
+// IsInt checks if a variable is an integer
+// is_int(mixed $value): bool
+func IsInt(val *types.Value) *types.Value {
+	return types.NewBool(val.Type() == types.TypeInt)
+}
+
+// IsLong is an alias for IsInt
+func IsLong(val *types.Value) *types.Value {
+	return IsInt(val)
+}
+
+// IsInteger is an alias for IsInt
+func IsInteger(val *types.Value) *types.Value {
+	return IsInt(val)
+}
+
+// IsFloat checks if a variable is a float
+// is_float(mixed $value): bool
+func IsFloat(val *types.Value) *types.Value {
+	return types.NewBool(val.Type() == types.TypeFloat)
+}
+
+// IsDouble is an alias for IsFloat
+func IsDouble(val *types.Value) *types.Value {
+	return IsFloat(val)
+}
+
+// IsReal is an alias for IsFloat (deprecated but still in PHP)
+func IsReal(val *types.Value) *types.Value {
+	return IsFloat(val)
+}
+
+// IsString checks if a variable is a string
+// is_string(mixed $value): bool
+func IsString(val *types.Value) *types.Value {
+	return types.NewBool(val.Type() == types.TypeString)
+}
+
+// IsArray checks if a variable is an array
+// is_array(mixed $value): bool
+func IsArray(val *types.Value) *types.Value {
+	return types.NewBool(val.Type() == types.TypeArray)
+}
+
+// IsObject checks if a variable is an object
+// is_object(mixed $value): bool
+func IsObject(val *types.Value) *types.Value {
+	return types.NewBool(val.Type() == types.TypeObject)
+}
+
+// IsNumeric checks if a variable is a number or a numeric string, which
+// since PHP 8 may have whitespace before and after the number ("1e3 ",
+// " .5"). Leading-numeric strings ("12abc") and hexadecimal ones are not
+// numeric.
+// is_numeric(mixed $value): bool
+func IsNumeric(val *types.Value) *types.Value {
+	switch val.Type() {
+	case types.TypeInt, types.TypeFloat:
+		return types.NewBool(true)
+	case types.TypeString:
+		_, kind := types.ParseNumeric(val.ToString())
+		return types.NewBool(kind == types.Numeric)
+	default:
+		return types.NewBool(false)
+	}
+}
+
+// IsScalar checks if a variable is a scalar (int, float, string, or bool)
+// is_scalar(mixed $value): bool
+func IsScalar(val *types.Value) *types.Value {
+	return types.NewBool(val.IsScalar())
+}
+
+// IsIterable checks if a variable can be iterated over: an array or a
+// Traversable object
+// is_iterable(mixed $value): bool
+func IsIterable(val *types.Value) *types.Value {
+	switch val.Type() {
+	case types.TypeArray:
+		return types.NewBool(true)
+	case types.TypeObject:
+		return types.NewBool(implements(val.ToObject(), "Traversable"))
+	default:
+		return types.NewBool(false)
+	}
+}
+
+// IsCountable checks if a variable can be counted: an array or a
+// Countable object
+// is_countable(mixed $value): bool
+func IsCountable(val *types.Value) *types.Value {
+	switch val.Type() {
+	case types.TypeArray:
+		return types.NewBool(true)
+	case types.TypeObject:
+		return types.NewBool(implements(val.ToObject(), "Countable"))
+	default:
+		return types.NewBool(false)
+	}
+}
+
+// implements reports whether an object's class implements an interface
+func implements(obj *types.Object, iface string) bool {
+	return obj.ClassEntry != nil && obj.ClassEntry.ImplementsInterface(iface)
+}
+
+// ============================================================================
+// Type Names
+// ============================================================================
+
+// GetType returns the type of a variable, with the historical names of
+// gettype() ("boolean", "integer", "double", "NULL")
+// gettype(mixed $value): string
+func GetType(val *types.Value) *types.Value {
+	switch val.Type() {
+	case types.TypeNull:
+		return types.NewString("NULL")
+	case types.TypeBool:
+		return types.NewString("boolean")
+	case types.TypeInt:
+		return types.NewString("integer")
+	case types.TypeFloat:
+		return types.NewString("double")
+	case types.TypeString:
+		return types.NewString("string")
+	case types.TypeArray:
+		return types.NewString("array")
+	case types.TypeObject:
+		return types.NewString("object")
+	case types.TypeResource:
+		if val.ToResource().IsClosed() {
+			return types.NewString("resource (closed)")
+		}
+		return types.NewString("resource")
+	default:
+		return types.NewString("unknown type")
+	}
+}
+
+// GetDebugType returns the type of a variable as type declarations and
+// error messages name it: the class name of objects and the resource type
+// of resources
+// get_debug_type(mixed $value): string
+func GetDebugType(val *types.Value) *types.Value {
+	switch val.Type() {
+	case types.TypeObject:
+		name, _, _ := strings.Cut(val.ToObject().ClassName, "\x00")
+		return types.NewString(name)
+	case types.TypeResource:
+		res := val.ToResource()
+		if res.IsClosed() {
+			return types.NewString("resource (closed)")
+		}
+		return types.NewString("resource (" + res.Type() + ")")
+	default:
+		return types.NewString(val.TypeName())
+	}
+}
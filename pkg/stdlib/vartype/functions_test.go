@@ -0,0 +1,348 @@
+package vartype
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Type Checking Tests
+// ============================================================================
+
+func TestIsNull(t *testing.T) {
+	tests := []struct {
+		value    *types.Value
+		expected bool
+	}{
+		{types.NewNull(), true},
+		{types.NewBool(false), false},
+		{types.NewInt(0), false},
+		{types.NewString(""), false},
+	}
+
+	for _, tt := range tests {
+		result := IsNull(tt.value)
+		if result.ToBool() != tt.expected {
+			t.Errorf("IsNull(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
+		}
+	}
+}
+
+func TestIsBool(t *testing.T) {
+	tests := []struct {
+		value    *types.Value
+		expected bool
+	}{
+		{types.NewBool(true), true},
+		{types.NewBool(false), true},
+		{types.NewInt(1), false},
+		{types.NewString("true"), false},
+	}
+
+	for _, tt := range tests {
+		result := IsBool(tt.value)
+		if result.ToBool() != tt.expected {
+			t.Errorf("IsBool(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
+		}
+	}
+}
+
+func TestIsInt(t *testing.T) {
+	tests := []struct {
+		value    *types.Value
+		expected bool
+	}{
+		{types.NewInt(42), true},
+		{types.NewInt(0), true},
+		{types.NewInt(-100), true},
+		{types.NewFloat(42.0), false},
+		{types.NewString("42"), false},
+	}
+
+	for _, tt := range tests {
+		result := IsInt(tt.value)
+		if result.ToBool() != tt.expected {
+			t.Errorf("IsInt(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
+		}
+	}
+}
+
+func TestIsFloat(t *testing.T) {
+	tests := []struct {
+		value    *types.Value
+		expected bool
+	}{
+		{types.NewFloat(3.14), true},
+		{types.NewFloat(0.0), true},
+		{types.NewInt(42), false},
+		{types.NewString("3.14"), false},
+	}
+
+	for _, tt := range tests {
+		result := IsFloat(tt.value)
+		if result.ToBool() != tt.expected {
+			t.Errorf("IsFloat(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
+		}
+	}
+}
+
+func TestIsString(t *testing.T) {
+	tests := []struct {
+		value    *types.Value
+		expected bool
+	}{
+		{types.NewString("hello"), true},
+		{types.NewString(""), true},
+		{types.NewInt(42), false},
+		{types.NewBool(false), false},
+	}
+
+	for _, tt := range tests {
+		result := IsString(tt.value)
+		if result.ToBool() != tt.expected {
+			t.Errorf("IsString(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
+		}
+	}
+}
+
+func TestIsArray(t *testing.T) {
+	arr := types.NewEmptyArray()
+
+	tests := []struct {
+		value    *types.Value
+		expected bool
+	}{
+		{types.NewArray(arr), true},
+		{types.NewString("array"), false},
+		{types.NewInt(0), false},
+	}
+
+	for _, tt := range tests {
+		result := IsArray(tt.value)
+		if result.ToBool() != tt.expected {
+			t.Errorf("IsArray(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
+		}
+	}
+}
+
+func TestIsObject(t *testing.T) {
+	// Create a simple class for testing
+	class := types.NewClassEntry("TestClass")
+	obj := types.NewObjectFromClass(class)
+
+	tests := []struct {
+		value    *types.Value
+		expected bool
+	}{
+		{types.NewObject(obj), true},
+		{types.NewString("object"), false},
+		{types.NewInt(0), false},
+	}
+
+	for _, tt := range tests {
+		result := IsObject(tt.value)
+		if result.ToBool() != tt.expected {
+			t.Errorf("IsObject(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
+		}
+	}
+}
+
+func TestIsNumeric(t *testing.T) {
+	tests := []struct {
+		value    *types.Value
+		expected bool
+	}{
+		{types.NewInt(42), true},
+		{types.NewFloat(3.14), true},
+		{types.NewString("42"), true},
+		{types.NewString("3.14"), true},
+		{types.NewString("hello"), false},
+		{types.NewBool(true), false},
+		// PHP 8 numeric strings
+		{types.NewString(" 42"), true},
+		{types.NewString("42 "), true},
+		{types.NewString("1e3"), true},
+		{types.NewString(".5"), true},
+		{types.NewString("-1.5E-3"), true},
+		{types.NewString("42abc"), false},
+		{types.NewString("0x1A"), false},
+		{types.NewString(""), false},
+		{types.NewString(" "), false},
+		{types.NewString("."), false},
+	}
+
+	for _, tt := range tests {
+		result := IsNumeric(tt.value)
+		if result.ToBool() != tt.expected {
+			t.Errorf("IsNumeric(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
+		}
+	}
+}
+
+func TestIsScalar(t *testing.T) {
+	arr := types.NewEmptyArray()
+
+	tests := []struct {
+		value    *types.Value
+		expected bool
+	}{
+		{types.NewInt(42), true},
+		{types.NewFloat(3.14), true},
+		{types.NewString("hello"), true},
+		{types.NewBool(true), true},
+		{types.NewArray(arr), false},
+		{types.NewNull(), false},
+	}
+
+	for _, tt := range tests {
+		result := IsScalar(tt.value)
+		if result.ToBool() != tt.expected {
+			t.Errorf("IsScalar(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
+		}
+	}
+}
+
+func TestIsIterable(t *testing.T) {
+	arr := types.NewEmptyArray()
+	class := types.NewClassEntry("TestClass")
+	obj := types.NewObjectFromClass(class)
+	iterator := types.NewClassEntry("TestIterator")
+	iterator.Interfaces = []*types.InterfaceEntry{{Name: "Iterator", ParentInterfaces: []*types.InterfaceEntry{{Name: "Traversable"}}}}
+
+	tests := []struct {
+		value    *types.Value
+		expected bool
+	}{
+		{types.NewArray(arr), true},
+		{types.NewObject(types.NewObjectFromClass(iterator)), true},
+		{types.NewObject(obj), false},
+		{types.NewString("hello"), false},
+		{types.NewInt(42), false},
+	}
+
+	for _, tt := range tests {
+		result := IsIterable(tt.value)
+		if result.ToBool() != tt.expected {
+			t.Errorf("IsIterable(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
+		}
+	}
+}
+
+func TestGetType(t *testing.T) {
+	arr := types.NewEmptyArray()
+	class := types.NewClassEntry("TestClass")
+	obj := types.NewObjectFromClass(class)
+	res := types.NewResourceHandle("test", "data")
+
+	tests := []struct {
+		value    *types.Value
+		expected string
+	}{
+		{types.NewNull(), "NULL"},
+		{types.NewBool(true), "boolean"},
+		{types.NewInt(42), "integer"},
+		{types.NewFloat(3.14), "double"},
+		{types.NewString("hello"), "string"},
+		{types.NewArray(arr), "array"},
+		{types.NewObject(obj), "object"},
+		{types.NewResource(res), "resource"},
+	}
+
+	for _, tt := range tests {
+		result := GetType(tt.value)
+		if result.ToString() != tt.expected {
+			t.Errorf("GetType(%v) = %v, want %v", tt.value, result.ToString(), tt.expected)
+		}
+	}
+
+	res.Close()
+	if got := GetType(types.NewResource(res)).ToString(); got != "resource (closed)" {
+		t.Errorf("GetType(closed) = %q, want \"resource (closed)\"", got)
+	}
+}
+
+func TestIsCountable(t *testing.T) {
+	counter := types.NewClassEntry("Counter")
+	counter.Interfaces = []*types.InterfaceEntry{{Name: "Countable"}}
+	counter.Methods["count"] = &types.MethodDef{Name: "count"}
+	plain := types.NewClassEntry("Plain")
+	plain.Methods["count"] = &types.MethodDef{Name: "count"}
+
+	tests := []struct {
+		value    *types.Value
+		expected bool
+	}{
+		{types.NewArray(types.NewEmptyArray()), true},
+		{types.NewObject(types.NewObjectFromClass(counter)), true},
+		{types.NewObject(types.NewObjectFromClass(plain)), false},
+		{types.NewString("abc"), false},
+	}
+
+	for _, tt := range tests {
+		if result := IsCountable(tt.value); result.ToBool() != tt.expected {
+			t.Errorf("IsCountable(%v) = %v, want %v", tt.value, result.ToBool(), tt.expected)
+		}
+	}
+}
+
+func TestGetDebugType(t *testing.T) {
+	res := types.NewResourceHandle("stream", nil)
+
+	tests := []struct {
+		value    *types.Value
+		expected string
+	}{
+		{types.NewNull(), "null"},
+		{types.NewBool(false), "bool"},
+		{types.NewInt(1), "int"},
+		{types.NewFloat(1.5), "float"},
+		{types.NewString(""), "string"},
+		{types.NewArray(types.NewEmptyArray()), "array"},
+		{types.NewObject(types.NewObjectFromClass(types.NewClassEntry("App\\User"))), "App\\User"},
+		{types.NewResource(res), "resource (stream)"},
+	}
+
+	for _, tt := range tests {
+		if got := GetDebugType(tt.value).ToString(); got != tt.expected {
+			t.Errorf("GetDebugType(%v) = %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+
+	res.Close()
+	if got := GetDebugType(types.NewResource(res)).ToString(); got != "resource (closed)" {
+		t.Errorf("GetDebugType(closed) = %q", got)
+	}
+}
+
+// ============================================================================
+// Alias Tests
+// ============================================================================
+
+func TestIsLong(t *testing.T) {
+	result := IsLong(types.NewInt(42))
+	if !result.ToBool() {
+		t.Errorf("IsLong(42) should return true")
+	}
+}
+
+func TestIsInteger(t *testing.T) {
+	result := IsInteger(types.NewInt(42))
+	if !result.ToBool() {
+		t.Errorf("IsInteger(42) should return true")
+	}
+}
+
+func TestIsDouble(t *testing.T) {
+	result := IsDouble(types.NewFloat(3.14))
+	if !result.ToBool() {
+		t.Errorf("IsDouble(3.14) should return true")
+	}
+}
+
+func TestIsReal(t *testing.T) {
+	result := IsReal(types.NewFloat(3.14))
+	if !result.ToBool() {
+		t.Errorf("IsReal(3.14) should return true")
+	}
+}
@@ -1,85 +1,398 @@
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync/atomic"
+)
 
 // Array represents a PHP array (ordered associative array)
 // PHP arrays are ordered maps that can have both integer and string keys
 // and preserve insertion order.
+//
+// Arrays have value semantics implemented with copy-on-write: Copy returns a
+// new handle sharing the same storage in O(1), and the storage is duplicated
+// only when a handle that shares it is modified.
 type Array struct {
-	// For packed arrays (sequential integer keys starting from 0)
+	data *arrayData
+}
+
+// arrayData is the storage shared between array handles. Like zend_hash it
+// has two layouts:
+//
+//   - packed: the keys are exactly 0..n-1 in order, so values are stored in a
+//     plain slice and the key is the position
+//   - hash: buckets in insertion order plus an index from key to bucket.
+//     Deleted buckets are left as holes (nil value) so positions stay stable,
+//     and are compacted away once they outnumber the live elements.
+//
+// Packed arrays switch to the hash layout on the first insertion that would
+// break the 0..n-1 sequence and on unset.
+type arrayData struct {
+	// refcount is the number of handles sharing this storage
+	refcount atomic.Int32
+
+	// Packed layout
 	packed     bool
 	packedData []*Value
 
-	// For hash tables (string keys or non-sequential integer keys)
-	elements map[interface{}]*Value
-	order    []interface{}
+	// Hash layout
+	buckets []bucket
+	index   map[arrayKey]int
+	used    int // Number of live buckets
 
-	// Next auto-index for append operations
+	// nextIndex is the key used by the next append ($a[] = ...) in the hash
+	// layout, or noNextIndex if no integer key was inserted yet
 	nextIndex int64
 }
 
+// arrayKey is a normalized array key: an integer or a string
+type arrayKey struct {
+	str   string
+	num   int64
+	isStr bool
+}
+
+// bucket is an element slot of the hash layout
+type bucket struct {
+	key arrayKey
+	val *Value // nil marks a deleted element
+}
+
+// noNextIndex marks a hash array that never had an integer key. The next
+// append then uses key 0, and a first negative key n makes it n+1.
+const noNextIndex = math.MinInt64
+
+// intKey creates an integer array key
+func intKey(n int64) arrayKey {
+	return arrayKey{num: n}
+}
+
+// strKey creates a string array key
+func strKey(s string) arrayKey {
+	return arrayKey{str: s, isStr: true}
+}
+
+// toValue converts the key to a PHP value
+func (k arrayKey) toValue() *Value {
+	if k.isStr {
+		return NewString(k.str)
+	}
+	return NewInt(k.num)
+}
+
+// String returns the key as text
+func (k arrayKey) String() string {
+	if k.isStr {
+		return k.str
+	}
+	return strconv.FormatInt(k.num, 10)
+}
+
+// newArray wraps storage in a handle that owns it exclusively
+func newArray(data *arrayData) *Array {
+	data.refcount.Store(1)
+	return &Array{data: data}
+}
+
+// newHashData creates empty storage in the hash layout
+func newHashData(capacity int) *arrayData {
+	return &arrayData{
+		buckets:   make([]bucket, 0, capacity),
+		index:     make(map[arrayKey]int, capacity),
+		nextIndex: noNextIndex,
+	}
+}
+
 // ============================================================================
 // Constructors
 // ============================================================================
 
 // NewEmptyArray creates a new empty array
 func NewEmptyArray() *Array {
-	return &Array{
+	return newArray(&arrayData{
 		packed:     true, // Start as packed, demote to hash if needed
 		packedData: make([]*Value, 0, 8),
-		elements:   nil,
-		order:      nil,
-		nextIndex:  0,
-	}
+	})
 }
 
 // NewArrayWithCapacity creates a new array with pre-allocated capacity
 func NewArrayWithCapacity(capacity int) *Array {
-	return &Array{
+	return newArray(&arrayData{
 		packed:     true,
 		packedData: make([]*Value, 0, capacity),
-		elements:   nil,
-		order:      nil,
-		nextIndex:  0,
-	}
+	})
 }
 
 // NewArrayFromSlice creates an array from a slice of values
 func NewArrayFromSlice(values []*Value) *Array {
-	arr := &Array{
+	d := &arrayData{
 		packed:     true,
 		packedData: make([]*Value, len(values)),
-		elements:   nil,
-		order:      nil,
-		nextIndex:  int64(len(values)),
 	}
-	copy(arr.packedData, values)
-	return arr
+	copy(d.packedData, values)
+	return newArray(d)
 }
 
-// NewArrayFromMap creates an array from a map
+// NewArrayFromMap creates an array from a map. Keys are normalized like any
+// other array key. Go maps are unordered, so integer keys come first in
+// ascending order, followed by string keys in lexical order.
 func NewArrayFromMap(data map[interface{}]*Value) *Array {
-	arr := &Array{
-		packed:     false,
-		packedData: nil,
-		elements:   make(map[interface{}]*Value),
-		order:      make([]interface{}, 0, len(data)),
-		nextIndex:  0,
+	keys := make([]arrayKey, 0, len(data))
+	values := make(map[arrayKey]*Value, len(data))
+	for k, v := range data {
+		key := keyFromInterface(k)
+		if _, exists := values[key]; !exists {
+			keys = append(keys, key)
+		}
+		values[key] = v
 	}
 
-	maxIndex := int64(-1)
-	for k, v := range data {
-		arr.order = append(arr.order, k)
-		arr.elements[k] = v
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].isStr != keys[j].isStr {
+			return !keys[i].isStr
+		}
+		if keys[i].isStr {
+			return keys[i].str < keys[j].str
+		}
+		return keys[i].num < keys[j].num
+	})
+
+	d := newHashData(len(keys))
+	for _, key := range keys {
+		d.insert(key, values[key])
+	}
+	return newArray(d)
+}
+
+// keyFromInterface normalizes a Go map key
+func keyFromInterface(k interface{}) arrayKey {
+	switch key := k.(type) {
+	case int64:
+		return intKey(key)
+	case int:
+		return intKey(int64(key))
+	case string:
+		return normalizeKey(NewString(key))
+	case *Value:
+		return normalizeKey(key)
+	default:
+		return strKey(fmt.Sprint(key))
+	}
+}
+
+// ============================================================================
+// Copy-on-Write
+// ============================================================================
+
+// Copy returns a copy of the array with PHP value semantics. The copy shares
+// storage with the original until either of them is modified.
+func (a *Array) Copy() *Array {
+	if a == nil || a.data == nil {
+		return NewEmptyArray()
+	}
+	a.data.refcount.Add(1)
+	return &Array{data: a.data}
+}
 
-		// Track next index for integer keys
-		if intKey, ok := k.(int64); ok && intKey > maxIndex {
-			maxIndex = intKey
+// IsShared reports whether the array storage is shared with other handles
+func (a *Array) IsShared() bool {
+	return a != nil && a.data != nil && a.data.refcount.Load() > 1
+}
+
+// Separate makes sure the array does not share its storage, so elements
+// fetched from it can be modified in place (FETCH_DIM_W)
+func (a *Array) Separate() {
+	if a != nil {
+		a.mutable()
+	}
+}
+
+// mutable returns storage that is safe to modify, separating the array from
+// the handles it shares storage with first
+func (a *Array) mutable() *arrayData {
+	if a.data == nil {
+		a.data = NewEmptyArray().data
+		return a.data
+	}
+	if a.data.refcount.Load() > 1 {
+		shared := a.data
+		a.data = shared.clone()
+		shared.refcount.Add(-1)
+	}
+	return a.data
+}
+
+// clone duplicates the storage for a separating handle. Values other than
+// arrays are immutable and shared; nested arrays get their own copy-on-write
+// handle, so they stay shared until they are modified.
+func (d *arrayData) clone() *arrayData {
+	if d.packed {
+		cloned := &arrayData{
+			packed:     true,
+			packedData: make([]*Value, len(d.packedData), cap(d.packedData)),
 		}
+		cloned.refcount.Store(1)
+		for i, v := range d.packedData {
+			cloned.packedData[i] = cloneElement(v)
+		}
+		return cloned
+	}
+
+	// Cloning also drops the holes left by deleted elements
+	cloned := newHashData(d.used)
+	cloned.refcount.Store(1)
+	for _, b := range d.buckets {
+		if b.val != nil {
+			cloned.index[b.key] = len(cloned.buckets)
+			cloned.buckets = append(cloned.buckets, bucket{key: b.key, val: cloneElement(b.val)})
+		}
+	}
+	cloned.used = len(cloned.buckets)
+	cloned.nextIndex = d.nextIndex
+	return cloned
+}
+
+// cloneElement copies an element into separated storage, which becomes
+// another holder of an object element
+func cloneElement(v *Value) *Value {
+	if v != nil && v.typ == TypeArray {
+		return v.Copy()
+	}
+	AddRef(v)
+	return v
+}
+
+// ============================================================================
+// Hash Layout
+// ============================================================================
+
+// convertToHash converts a packed array to the hash layout
+func (d *arrayData) convertToHash() {
+	if !d.packed {
+		return
+	}
+
+	n := len(d.packedData)
+	d.buckets = make([]bucket, n, n+8)
+	d.index = make(map[arrayKey]int, n+8)
+	for i, v := range d.packedData {
+		key := intKey(int64(i))
+		d.buckets[i] = bucket{key: key, val: v}
+		d.index[key] = i
+	}
+	d.used = n
+	d.nextIndex = noNextIndex
+	if n > 0 {
+		d.nextIndex = int64(n)
+	}
+
+	d.packed = false
+	d.packedData = nil
+}
+
+// insert adds a key that is not in the array yet at the end of the
+// insertion order (hash layout)
+func (d *arrayData) insert(key arrayKey, value *Value) {
+	d.index[key] = len(d.buckets)
+	d.buckets = append(d.buckets, bucket{key: key, val: value})
+	d.used++
+
+	// The next append key is one past the largest integer key ever inserted
+	if !key.isStr && (d.nextIndex == noNextIndex || key.num >= d.nextIndex) {
+		if key.num == math.MaxInt64 {
+			d.nextIndex = math.MaxInt64
+		} else {
+			d.nextIndex = key.num + 1
+		}
+	}
+}
+
+// appendKey returns the key used by $a[] = ... (hash layout)
+func (d *arrayData) appendKey() arrayKey {
+	if d.nextIndex == noNextIndex {
+		return intKey(0)
+	}
+	return intKey(d.nextIndex)
+}
+
+// remove deletes the bucket at pos (hash layout)
+func (d *arrayData) remove(pos int) {
+	delete(d.index, d.buckets[pos].key)
+	d.buckets[pos] = bucket{}
+	d.used--
+
+	// Trailing holes are dropped right away, the others once they
+	// outnumber the live elements
+	for len(d.buckets) > 0 && d.buckets[len(d.buckets)-1].val == nil {
+		d.buckets = d.buckets[:len(d.buckets)-1]
+	}
+	if holes := len(d.buckets) - d.used; holes > 8 && holes > d.used {
+		d.compact()
+	}
+}
+
+// compact removes the holes left by deleted elements and rebuilds the index
+func (d *arrayData) compact() {
+	live := d.buckets[:0]
+	for _, b := range d.buckets {
+		if b.val != nil {
+			live = append(live, b)
+		}
+	}
+	// Clear the tail so removed values can be garbage collected
+	for i := len(live); i < len(d.buckets); i++ {
+		d.buckets[i] = bucket{}
+	}
+	d.buckets = live
+
+	d.index = make(map[arrayKey]int, len(live))
+	for i, b := range live {
+		d.index[b.key] = i
+	}
+}
+
+// reindex replaces the contents with entries in the hash layout, renumbering
+// integer keys from zero and keeping string keys, as array_shift() and
+// array_unshift() do
+func (d *arrayData) reindex(entries []bucket) {
+	d.packed = false
+	d.packedData = nil
+	d.buckets = make([]bucket, 0, len(entries)+8)
+	d.index = make(map[arrayKey]int, len(entries)+8)
+	d.used = 0
+	d.nextIndex = 0
+
+	next := int64(0)
+	for _, b := range entries {
+		key := b.key
+		if !key.isStr {
+			key = intKey(next)
+			next++
+		}
+		d.insert(key, b.val)
 	}
+}
 
-	arr.nextIndex = maxIndex + 1
-	return arr
+// entries returns the live elements in order
+func (d *arrayData) entries() []bucket {
+	if d.packed {
+		entries := make([]bucket, len(d.packedData))
+		for i, v := range d.packedData {
+			entries[i] = bucket{key: intKey(int64(i)), val: v}
+		}
+		return entries
+	}
+
+	entries := make([]bucket, 0, d.used)
+	for _, b := range d.buckets {
+		if b.val != nil {
+			entries = append(entries, b)
+		}
+	}
+	return entries
 }
 
 // ============================================================================
@@ -88,13 +401,13 @@ func NewArrayFromMap(data map[interface{}]*Value) *Array {
 
 // Len returns the number of elements in the array
 func (a *Array) Len() int {
-	if a == nil {
+	if a == nil || a.data == nil {
 		return 0
 	}
-	if a.packed {
-		return len(a.packedData)
+	if a.data.packed {
+		return len(a.data.packedData)
 	}
-	return len(a.elements)
+	return a.data.used
 }
 
 // IsEmpty returns true if the array is empty
@@ -104,68 +417,109 @@ func (a *Array) IsEmpty() bool {
 
 // IsPacked returns true if the array is using packed optimization
 func (a *Array) IsPacked() bool {
-	if a == nil {
+	if a == nil || a.data == nil {
 		return false
 	}
-	return a.packed
+	return a.data.packed
+}
+
+// NextIndex returns the key the next append ($a[] = ...) will use
+func (a *Array) NextIndex() int64 {
+	if a == nil || a.data == nil {
+		return 0
+	}
+	if a.data.packed {
+		return int64(len(a.data.packedData))
+	}
+	return a.data.appendKey().num
 }
 
 // ============================================================================
 // Key Normalization
 // ============================================================================
 
+// NormalizeKey returns the key a value is stored under in an array:
+// "5" becomes 5, true becomes 1, null becomes "" and so on
+func NormalizeKey(key *Value) *Value {
+	return normalizeKey(key).toValue()
+}
+
 // normalizeKey converts a Value to an appropriate array key
 // Following PHP's key conversion rules:
 // - Integers stay as int64
-// - Numeric strings become integers ("42" -> 42)
+// - Decimal integer strings become integers ("42" -> 42, but "042", "4.2" and " 42" stay strings)
 // - Floats become integers (truncated)
 // - Booleans: true->1, false->0
 // - Null becomes empty string
-func normalizeKey(key *Value) interface{} {
+// - Resources use their ID
+func normalizeKey(key *Value) arrayKey {
+	key = key.Deref()
+
 	switch key.Type() {
 	case TypeInt:
-		return key.ToInt()
+		return intKey(key.ToInt())
 
 	case TypeFloat:
-		// Float keys are truncated to integers
-		return int64(key.ToFloat())
+		// Float keys are truncated; NaN and infinities become 0
+		f := key.ToFloat()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return intKey(0)
+		}
+		return intKey(int64(f))
 
 	case TypeBool:
 		// Boolean keys: true->1, false->0
 		if key.ToBool() {
-			return int64(1)
+			return intKey(1)
 		}
-		return int64(0)
+		return intKey(0)
 
-	case TypeNull:
+	case TypeUndef, TypeNull:
 		// Null becomes empty string
-		return ""
+		return strKey("")
 
 	case TypeString:
 		s := key.ToString()
-		// Try to convert to integer if it's a numeric string
-		if i, err := parseInt(s); err == nil {
-			return i
+		if i, ok := parseIntKey(s); ok {
+			return intKey(i)
 		}
-		return s
+		return strKey(s)
+
+	case TypeResource:
+		return intKey(key.ToInt())
 
 	default:
 		// Other types become strings
-		return key.ToString()
+		return strKey(key.ToString())
 	}
 }
 
-// parseInt attempts to parse a string as an integer
-func parseInt(s string) (int64, error) {
-	// Handle empty string
-	if s == "" {
-		return 0, fmt.Errorf("empty string")
+// parseIntKey reports whether s is a canonical decimal integer ("0", "42",
+// "-7") that fits in an int64. Leading zeros, "+", "-0", whitespace and
+// overflowing numbers keep the key a string.
+func parseIntKey(s string) (int64, bool) {
+	if s == "" || len(s) > 20 {
+		return 0, false
 	}
 
-	// Try parsing as integer
-	var result int64
-	_, err := fmt.Sscanf(s, "%d", &result)
-	return result, err
+	digits := s
+	if s[0] == '-' {
+		digits = s[1:]
+	}
+	if digits == "" || (digits[0] == '0' && len(s) > 1) {
+		return 0, false
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, false
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
 }
 
 // ============================================================================
@@ -174,28 +528,30 @@ func parseInt(s string) (int64, error) {
 
 // Get retrieves a value from the array
 func (a *Array) Get(key *Value) (*Value, bool) {
-	if a == nil {
+	if a == nil || a.data == nil {
 		return NewNull(), false
 	}
 
-	k := normalizeKey(key)
+	if val, ok := a.data.lookup(normalizeKey(key)); ok {
+		return val, true
+	}
+	return NewNull(), false
+}
 
+// lookup finds the value stored under a normalized key
+func (d *arrayData) lookup(k arrayKey) (*Value, bool) {
 	// Fast path for packed arrays with integer keys
-	if a.packed {
-		if intKey, ok := k.(int64); ok {
-			if intKey >= 0 && intKey < int64(len(a.packedData)) {
-				return a.packedData[intKey], true
-			}
+	if d.packed {
+		if !k.isStr && k.num >= 0 && k.num < int64(len(d.packedData)) {
+			return d.packedData[k.num], true
 		}
-		return NewNull(), false
+		return nil, false
 	}
 
-	// Hash table lookup
-	val, exists := a.elements[k]
-	if !exists {
-		return NewNull(), false
+	if pos, ok := d.index[k]; ok {
+		return d.buckets[pos].val, true
 	}
-	return val, true
+	return nil, false
 }
 
 // Set adds or updates a value in the array
@@ -204,39 +560,38 @@ func (a *Array) Set(key *Value, value *Value) {
 		return
 	}
 
+	d := a.mutable()
 	k := normalizeKey(key)
+	AddRef(value)
 
 	// Try to keep packed optimization
-	if a.packed {
-		if intKey, ok := k.(int64); ok {
+	if d.packed {
+		if !k.isStr {
 			// Check if we can append to packed array
-			if intKey == int64(len(a.packedData)) {
-				a.packedData = append(a.packedData, value)
-				a.nextIndex = intKey + 1
+			if k.num == int64(len(d.packedData)) {
+				d.packedData = append(d.packedData, value)
 				return
 			}
 
 			// Check if it's an update to existing packed element
-			if intKey >= 0 && intKey < int64(len(a.packedData)) {
-				a.packedData[intKey] = value
+			if k.num >= 0 && k.num < int64(len(d.packedData)) {
+				DelRef(d.packedData[k.num])
+				d.packedData[k.num] = value
 				return
 			}
 		}
 
 		// Need to convert to hash table
-		a.convertToHash()
+		d.convertToHash()
 	}
 
-	// Add to hash table
-	if _, exists := a.elements[k]; !exists {
-		a.order = append(a.order, k)
-	}
-	a.elements[k] = value
-
-	// Update next index if this is an integer key
-	if intKey, ok := k.(int64); ok && intKey >= a.nextIndex {
-		a.nextIndex = intKey + 1
+	// Updates keep the element's position in the order
+	if pos, exists := d.index[k]; exists {
+		DelRef(d.buckets[pos].val)
+		d.buckets[pos].val = value
+		return
 	}
+	d.insert(k, value)
 }
 
 // Append adds a value to the end of the array with auto-incrementing index
@@ -245,43 +600,39 @@ func (a *Array) Append(value *Value) {
 		return
 	}
 
-	if a.packed {
-		a.packedData = append(a.packedData, value)
-		a.nextIndex++
+	d := a.mutable()
+	if d.packed {
+		AddRef(value)
+		d.packedData = append(d.packedData, value)
 		return
 	}
 
-	// Hash table append
-	key := a.nextIndex
-	a.order = append(a.order, key)
-	a.elements[key] = value
-	a.nextIndex++
+	// Once the next index is past PHP_INT_MAX the append is dropped; PHP
+	// reports "Cannot add element to the array as the next element is
+	// already occupied"
+	key := d.appendKey()
+	if _, exists := d.index[key]; exists {
+		return
+	}
+	AddRef(value)
+	d.insert(key, value)
 }
 
 // Unset removes a key from the array
 func (a *Array) Unset(key *Value) {
-	if a == nil {
+	if a == nil || !a.HasKey(key) {
 		return
 	}
 
-	k := normalizeKey(key)
-
-	if a.packed {
-		// For packed arrays, unset converts to hash
-		a.convertToHash()
-	}
+	d := a.mutable()
 
-	// Remove from hash table
-	if _, exists := a.elements[k]; exists {
-		delete(a.elements, k)
+	// The key of an unset element is not reused by later appends, which
+	// the packed layout cannot represent
+	d.convertToHash()
 
-		// Remove from order
-		for i, orderKey := range a.order {
-			if orderKey == k {
-				a.order = append(a.order[:i], a.order[i+1:]...)
-				break
-			}
-		}
+	if pos, exists := d.index[normalizeKey(key)]; exists {
+		DelRef(d.buckets[pos].val)
+		d.remove(pos)
 	}
 }
 
@@ -297,99 +648,83 @@ func (a *Array) Push(values ...*Value) int {
 	return a.Len()
 }
 
-// Pop removes and returns the last element
+// Pop removes and returns the last element (array_pop). When it had the
+// largest integer key, the next append reuses that key.
 func (a *Array) Pop() (*Value, bool) {
 	if a.IsEmpty() {
 		return NewNull(), false
 	}
 
-	if a.packed {
-		lastIdx := len(a.packedData) - 1
-		value := a.packedData[lastIdx]
-		a.packedData = a.packedData[:lastIdx]
-		a.nextIndex--
+	d := a.mutable()
+	if d.packed {
+		lastIdx := len(d.packedData) - 1
+		value := d.packedData[lastIdx]
+		d.packedData[lastIdx] = nil
+		d.packedData = d.packedData[:lastIdx]
 		return value, true
 	}
 
-	// Hash table pop
-	if len(a.order) == 0 {
-		return NewNull(), false
+	pos := len(d.buckets) - 1
+	last := d.buckets[pos]
+	d.remove(pos)
+	if !last.key.isStr && d.nextIndex != noNextIndex && last.key.num == d.nextIndex-1 {
+		d.nextIndex--
 	}
-
-	lastKey := a.order[len(a.order)-1]
-	value := a.elements[lastKey]
-	delete(a.elements, lastKey)
-	a.order = a.order[:len(a.order)-1]
-
-	return value, true
+	return last.val, true
 }
 
-// Shift removes and returns the first element
+// Shift removes and returns the first element (array_shift). Integer keys
+// are renumbered from zero; string keys are kept.
 func (a *Array) Shift() (*Value, bool) {
 	if a.IsEmpty() {
 		return NewNull(), false
 	}
 
-	if a.packed {
-		if len(a.packedData) == 0 {
-			return NewNull(), false
-		}
-		value := a.packedData[0]
-		a.packedData = a.packedData[1:]
-		// Note: This doesn't reindex, which matches PHP behavior
+	d := a.mutable()
+	if d.packed {
+		value := d.packedData[0]
+		d.packedData = d.packedData[1:]
 		return value, true
 	}
 
-	// Hash table shift
-	if len(a.order) == 0 {
-		return NewNull(), false
-	}
-
-	firstKey := a.order[0]
-	value := a.elements[firstKey]
-	delete(a.elements, firstKey)
-	a.order = a.order[1:]
-
-	return value, true
+	entries := d.entries()
+	d.reindex(entries[1:])
+	return entries[0].val, true
 }
 
-// Unshift prepends one or more values to the beginning
+// Unshift prepends one or more values to the beginning (array_unshift).
+// Integer keys are renumbered from zero; string keys are kept.
 func (a *Array) Unshift(values ...*Value) int {
 	if len(values) == 0 {
 		return a.Len()
 	}
 
-	if a.packed {
+	d := a.mutable()
+	if d.packed {
 		// Prepend to packed array
-		newData := make([]*Value, len(values)+len(a.packedData))
+		newData := make([]*Value, len(values)+len(d.packedData))
 		copy(newData, values)
-		copy(newData[len(values):], a.packedData)
-		a.packedData = newData
-		return len(a.packedData)
+		copy(newData[len(values):], d.packedData)
+		d.packedData = newData
+		return len(d.packedData)
 	}
 
-	// Convert to hash table for unshift
-	a.convertToHash()
-
-	// Prepend to hash table
-	newOrder := make([]interface{}, 0, len(values)+len(a.order))
-	for i, v := range values {
-		key := int64(i)
-		newOrder = append(newOrder, key)
-		a.elements[key] = v
+	entries := make([]bucket, 0, len(values)+d.used)
+	for _, v := range values {
+		entries = append(entries, bucket{val: v})
 	}
-	newOrder = append(newOrder, a.order...)
-	a.order = newOrder
+	d.reindex(append(entries, d.entries()...))
 
-	return len(a.order)
+	return d.used
 }
 
-// Slice returns a portion of the array
+// Slice returns a portion of the array, preserving keys
 func (a *Array) Slice(offset, length int) *Array {
 	if a == nil || a.IsEmpty() {
 		return NewEmptyArray()
 	}
 
+	d := a.data
 	totalLen := a.Len()
 
 	// Handle negative offset
@@ -411,57 +746,55 @@ func (a *Array) Slice(offset, length int) *Array {
 		end = totalLen
 	}
 
-	if a.packed {
+	if d.packed {
 		// Slice packed array
-		sliced := a.packedData[offset:end]
+		sliced := d.packedData[offset:end]
 		return NewArrayFromSlice(sliced)
 	}
 
 	// Slice hash table
-	result := NewEmptyArray()
-	result.convertToHash()
-
-	for i := offset; i < end && i < len(a.order); i++ {
-		key := a.order[i]
-		result.order = append(result.order, key)
-		result.elements[key] = a.elements[key]
+	result := newHashData(end - offset)
+	for _, b := range d.entries()[offset:end] {
+		result.insert(b.key, b.val)
 	}
-
-	return result
+	return newArray(result)
 }
 
-// Merge combines this array with another
+// Merge combines this array with another (array_merge): string keys are
+// overwritten, integer keys are appended and renumbered
 func (a *Array) Merge(other *Array) *Array {
 	if a == nil {
 		if other == nil {
 			return NewEmptyArray()
 		}
-		return other.DeepCopy()
+		return other.Copy()
 	}
-	if other == nil {
-		return a.DeepCopy()
+	if other == nil || other.IsEmpty() {
+		return a.Copy()
 	}
 
-	result := a.DeepCopy()
+	// The result starts out sharing a's storage and separates on the first write
+	result := a.Copy()
 
 	// If other is packed, append its values
-	if other.packed {
-		for _, v := range other.packedData {
+	if other.data.packed {
+		for _, v := range other.data.packedData {
 			result.Append(v)
 		}
 		return result
 	}
 
 	// If other is a hash table, merge keys
-	for _, key := range other.order {
-		value := other.elements[key]
+	for _, b := range other.data.buckets {
+		if b.val == nil {
+			continue
+		}
 
 		// String keys are preserved, integer keys are reindexed
-		switch k := key.(type) {
-		case string:
-			result.Set(NewString(k), value)
-		case int64:
-			result.Append(value)
+		if b.key.isStr {
+			result.Set(NewString(b.key.str), b.val)
+		} else {
+			result.Append(b.val)
 		}
 	}
 
@@ -474,21 +807,19 @@ func (a *Array) Keys() *Array {
 		return NewEmptyArray()
 	}
 
-	keys := NewEmptyArray()
+	d := a.data
+	keys := NewArrayWithCapacity(a.Len())
 
-	if a.packed {
-		for i := 0; i < len(a.packedData); i++ {
+	if d.packed {
+		for i := 0; i < len(d.packedData); i++ {
 			keys.Append(NewInt(int64(i)))
 		}
 		return keys
 	}
 
-	for _, key := range a.order {
-		switch k := key.(type) {
-		case int64:
-			keys.Append(NewInt(k))
-		case string:
-			keys.Append(NewString(k))
+	for _, b := range d.buckets {
+		if b.val != nil {
+			keys.Append(b.key.toValue())
 		}
 	}
 
@@ -501,17 +832,16 @@ func (a *Array) Values() *Array {
 		return NewEmptyArray()
 	}
 
-	values := NewEmptyArray()
-
-	if a.packed {
-		for _, v := range a.packedData {
-			values.Append(v)
-		}
-		return values
+	d := a.data
+	if d.packed {
+		return NewArrayFromSlice(d.packedData)
 	}
 
-	for _, key := range a.order {
-		values.Append(a.elements[key])
+	values := NewArrayWithCapacity(d.used)
+	for _, b := range d.buckets {
+		if b.val != nil {
+			values.Append(b.val)
+		}
 	}
 
 	return values
@@ -519,36 +849,19 @@ func (a *Array) Values() *Array {
 
 // Contains checks if a value exists in the array
 func (a *Array) Contains(needle *Value) bool {
-	if a == nil || a.IsEmpty() {
-		return false
-	}
-
-	if a.packed {
-		for _, v := range a.packedData {
-			if v.Equals(needle) {
-				return true
-			}
-		}
-		return false
-	}
-
-	for _, key := range a.order {
-		if a.elements[key].Equals(needle) {
-			return true
-		}
-	}
-
-	return false
+	_, found := a.Search(needle)
+	return found
 }
 
-// Search finds the first key for a given value
+// Search finds the first key for a value equal to needle (==)
 func (a *Array) Search(needle *Value) (*Value, bool) {
 	if a == nil || a.IsEmpty() {
 		return NewNull(), false
 	}
 
-	if a.packed {
-		for i, v := range a.packedData {
+	d := a.data
+	if d.packed {
+		for i, v := range d.packedData {
 			if v.Equals(needle) {
 				return NewInt(int64(i)), true
 			}
@@ -556,36 +869,37 @@ func (a *Array) Search(needle *Value) (*Value, bool) {
 		return NewBool(false), false
 	}
 
-	for _, key := range a.order {
-		if a.elements[key].Equals(needle) {
-			switch k := key.(type) {
-			case int64:
-				return NewInt(k), true
-			case string:
-				return NewString(k), true
-			}
+	for _, b := range d.buckets {
+		if b.val != nil && b.val.Equals(needle) {
+			return b.key.toValue(), true
 		}
 	}
 
 	return NewBool(false), false
 }
 
-// HasKey checks if a key exists in the array
-func (a *Array) HasKey(key *Value) bool {
-	if a == nil {
-		return false
+// SearchStrict finds the first key for a value identical to needle (===)
+func (a *Array) SearchStrict(needle *Value) (*Value, bool) {
+	var found *Value
+	a.Each(func(key, value *Value) bool {
+		if StrictEquals(value, needle) {
+			found = key
+		}
+		return found == nil
+	})
+	if found == nil {
+		return NewBool(false), false
 	}
+	return found, true
+}
 
-	k := normalizeKey(key)
-
-	if a.packed {
-		if intKey, ok := k.(int64); ok {
-			return intKey >= 0 && intKey < int64(len(a.packedData))
-		}
+// HasKey checks if a key exists in the array
+func (a *Array) HasKey(key *Value) bool {
+	if a == nil || a.data == nil {
 		return false
 	}
 
-	_, exists := a.elements[k]
+	_, exists := a.data.lookup(normalizeKey(key))
 	return exists
 }
 
@@ -599,8 +913,9 @@ func (a *Array) Each(fn func(key, value *Value) bool) {
 		return
 	}
 
-	if a.packed {
-		for i, v := range a.packedData {
+	d := a.data
+	if d.packed {
+		for i, v := range d.packedData {
 			if !fn(NewInt(int64(i)), v) {
 				break
 			}
@@ -608,80 +923,73 @@ func (a *Array) Each(fn func(key, value *Value) bool) {
 		return
 	}
 
-	for _, key := range a.order {
-		var keyVal *Value
-		switch k := key.(type) {
-		case int64:
-			keyVal = NewInt(k)
-		case string:
-			keyVal = NewString(k)
-		default:
+	for _, b := range d.buckets {
+		if b.val == nil {
 			continue
 		}
-
-		if !fn(keyVal, a.elements[key]) {
+		if !fn(b.key.toValue(), b.val) {
 			break
 		}
 	}
 }
 
+// EntryAt returns the element at position pos of the array, or the first
+// one after it if that element was deleted, and the position following the
+// element returned. ok is false once no element is left. Positions only
+// stay valid while the storage is not modified, so they walk a copy.
+func (a *Array) EntryAt(pos int) (key, value *Value, next int, ok bool) {
+	if a == nil || a.data == nil {
+		return nil, nil, pos, false
+	}
+
+	d := a.data
+	if d.packed {
+		if pos >= len(d.packedData) {
+			return nil, nil, pos, false
+		}
+		return NewInt(int64(pos)), d.packedData[pos], pos + 1, true
+	}
+
+	for ; pos < len(d.buckets); pos++ {
+		if b := d.buckets[pos]; b.val != nil {
+			return b.key.toValue(), b.val, pos + 1, true
+		}
+	}
+	return nil, nil, pos, false
+}
+
 // ============================================================================
 // Conversion and Copying
 // ============================================================================
 
-// DeepCopy creates a deep copy of the array
+// DeepCopy creates a deep copy of the array. Prefer Copy, which defers the
+// copy until the array is modified.
 func (a *Array) DeepCopy() *Array {
-	if a == nil {
+	if a == nil || a.data == nil {
 		return NewEmptyArray()
 	}
 
-	if a.packed {
-		copied := &Array{
+	d := a.data
+	if d.packed {
+		copied := &arrayData{
 			packed:     true,
-			packedData: make([]*Value, len(a.packedData)),
-			elements:   nil,
-			order:      nil,
-			nextIndex:  a.nextIndex,
+			packedData: make([]*Value, len(d.packedData)),
 		}
-		for i, v := range a.packedData {
+		for i, v := range d.packedData {
 			copied.packedData[i] = v.DeepCopy()
 		}
-		return copied
-	}
-
-	copied := &Array{
-		packed:     false,
-		packedData: nil,
-		elements:   make(map[interface{}]*Value, len(a.elements)),
-		order:      make([]interface{}, len(a.order)),
-		nextIndex:  a.nextIndex,
-	}
-
-	copy(copied.order, a.order)
-	for k, v := range a.elements {
-		copied.elements[k] = v.DeepCopy()
+		return newArray(copied)
 	}
 
-	return copied
-}
-
-// convertToHash converts a packed array to a hash table
-func (a *Array) convertToHash() {
-	if !a.packed {
-		return
-	}
-
-	a.elements = make(map[interface{}]*Value, len(a.packedData))
-	a.order = make([]interface{}, len(a.packedData))
-
-	for i, v := range a.packedData {
-		key := int64(i)
-		a.elements[key] = v
-		a.order[i] = key
+	copied := newHashData(d.used)
+	for _, b := range d.buckets {
+		if b.val != nil {
+			copied.insert(b.key, b.val.DeepCopy())
+		}
 	}
+	copied.nextIndex = d.nextIndex
 
-	a.packed = false
-	a.packedData = nil
+	return newArray(copied)
 }
 
 // Reset resets the array to empty
@@ -690,11 +998,11 @@ func (a *Array) Reset() {
 		return
 	}
 
-	a.packed = true
-	a.packedData = make([]*Value, 0, 8)
-	a.elements = nil
-	a.order = nil
-	a.nextIndex = 0
+	// Other handles keep the old storage
+	if a.data != nil && a.data.refcount.Load() > 1 {
+		a.data.refcount.Add(-1)
+	}
+	a.data = NewEmptyArray().data
 }
 
 // ============================================================================
@@ -709,24 +1017,14 @@ func (a *Array) String() string {
 
 	result := "["
 	first := true
-
-	if a.packed {
-		for i, v := range a.packedData {
-			if !first {
-				result += ", "
-			}
-			result += fmt.Sprintf("%d => %v", i, v)
-			first = false
+	a.Each(func(key, value *Value) bool {
+		if !first {
+			result += ", "
 		}
-	} else {
-		for _, key := range a.order {
-			if !first {
-				result += ", "
-			}
-			result += fmt.Sprintf("%v => %v", key, a.elements[key])
-			first = false
-		}
-	}
+		result += fmt.Sprintf("%s => %v", key.ToString(), value)
+		first = false
+		return true
+	})
 
 	result += "]"
 	return result
@@ -0,0 +1,80 @@
+package types
+
+import (
+	"testing"
+)
+
+// newBenchArray creates a packed array of n integers
+func newBenchArray(n int) *Array {
+	arr := NewArrayWithCapacity(n)
+	for i := 0; i < n; i++ {
+		arr.Append(NewInt(int64(i)))
+	}
+	return arr
+}
+
+// BenchmarkArrayAssignCOW benchmarks $b = $a on a large array with copy-on-write
+func BenchmarkArrayAssignCOW(b *testing.B) {
+	arr := newBenchArray(10000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = arr.Copy()
+	}
+}
+
+// BenchmarkArrayAssignDeepCopy benchmarks $b = $a on a large array with an eager copy
+func BenchmarkArrayAssignDeepCopy(b *testing.B) {
+	arr := newBenchArray(10000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = arr.DeepCopy()
+	}
+}
+
+// BenchmarkArrayPassAndRead benchmarks passing a large array to a function that only reads it
+func BenchmarkArrayPassAndRead(b *testing.B) {
+	arr := NewArray(newBenchArray(10000))
+	sum := func(v *Value) int64 {
+		total := int64(0)
+		v.ToArray().Each(func(_, value *Value) bool {
+			total += value.ToInt()
+			return true
+		})
+		return total
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sum(arr.Copy())
+	}
+}
+
+// BenchmarkArrayCopyThenWrite benchmarks a copy followed by a single write,
+// which pays for the separation once
+func BenchmarkArrayCopyThenWrite(b *testing.B) {
+	arr := newBenchArray(10000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copied := arr.Copy()
+		copied.Set(NewInt(0), NewInt(-1))
+	}
+}
+
+// BenchmarkArrayMerge benchmarks array_merge of a large array with a small one
+func BenchmarkArrayMerge(b *testing.B) {
+	large := newBenchArray(10000)
+	small := newBenchArray(10)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = large.Merge(small)
+	}
+}
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -760,3 +762,243 @@ func TestArrayMixedKeys(t *testing.T) {
 		t.Error("String key 'name' failed")
 	}
 }
+
+// ============================================================================
+// Copy-on-Write Tests
+// ============================================================================
+
+func TestArrayCopyOnWrite(t *testing.T) {
+	arr := NewEmptyArray()
+	arr.Push(NewInt(1), NewInt(2), NewInt(3))
+
+	copied := arr.Copy()
+	if !arr.IsShared() || !copied.IsShared() {
+		t.Fatal("Copy should share storage until modified")
+	}
+
+	copied.Set(NewInt(0), NewInt(999))
+	copied.Append(NewInt(4))
+
+	if arr.IsShared() || copied.IsShared() {
+		t.Error("Modifying the copy should separate the storage")
+	}
+	if val, _ := arr.Get(NewInt(0)); val.ToInt() != 1 || arr.Len() != 3 {
+		t.Errorf("Original array should be unchanged, got %s", arr)
+	}
+	if val, _ := copied.Get(NewInt(0)); val.ToInt() != 999 || copied.Len() != 4 {
+		t.Errorf("Copy should be modified, got %s", copied)
+	}
+}
+
+func TestArrayCopyOnWriteOriginalModified(t *testing.T) {
+	arr := NewEmptyArray()
+	arr.Set(NewString("a"), NewInt(1))
+	copied := arr.Copy()
+
+	arr.Unset(NewString("a"))
+	arr.Set(NewString("b"), NewInt(2))
+
+	if !copied.HasKey(NewString("a")) || copied.HasKey(NewString("b")) {
+		t.Errorf("Copy should keep the original contents, got %s", copied)
+	}
+}
+
+func TestArrayCopyOnWriteNested(t *testing.T) {
+	inner := NewArrayFromSlice([]*Value{NewInt(1)})
+	outer := NewEmptyArray()
+	outer.Set(NewString("inner"), NewArray(inner))
+
+	copied := outer.Copy()
+
+	// $copied['inner'][] = 2
+	copied.Separate()
+	nested, _ := copied.Get(NewString("inner"))
+	nested.ToArray().Append(NewInt(2))
+
+	original, _ := outer.Get(NewString("inner"))
+	if original.ToArray().Len() != 1 {
+		t.Errorf("Nested array of the original should be unchanged, got %s", original.ToArray())
+	}
+	if nested.ToArray().Len() != 2 {
+		t.Errorf("Nested array of the copy should be modified, got %s", nested.ToArray())
+	}
+}
+
+func TestArrayMergeDoesNotModifyOperands(t *testing.T) {
+	a := NewArrayFromSlice([]*Value{NewInt(1)})
+	b := NewArrayFromSlice([]*Value{NewInt(2)})
+
+	merged := a.Merge(b)
+
+	if a.Len() != 1 || b.Len() != 1 || merged.Len() != 2 {
+		t.Errorf("Expected operands of length 1 and result of length 2, got %d, %d, %d", a.Len(), b.Len(), merged.Len())
+	}
+}
+
+func TestValueCopyArray(t *testing.T) {
+	v := NewArray(NewArrayFromSlice([]*Value{NewInt(1)}))
+	copied := v.Copy()
+
+	copied.ToArray().Append(NewInt(2))
+
+	if v.ToArray().Len() != 1 {
+		t.Error("Value.Copy should give arrays value semantics")
+	}
+}
+
+// ============================================================================
+// Ordered Hash Map
+// ============================================================================
+
+func TestArrayKeyCoercionEdgeCases(t *testing.T) {
+	tests := []struct {
+		key      *Value
+		expected *Value
+	}{
+		{NewString("5"), NewInt(5)},
+		{NewString("-5"), NewInt(-5)},
+		{NewString("0"), NewInt(0)},
+		{NewString("05"), NewString("05")},
+		{NewString("-0"), NewString("-0")},
+		{NewString("+5"), NewString("+5")},
+		{NewString(" 5"), NewString(" 5")},
+		{NewString("5 "), NewString("5 ")},
+		{NewString("1.5"), NewString("1.5")},
+		{NewString("9223372036854775807"), NewInt(9223372036854775807)},
+		{NewString("9223372036854775808"), NewString("9223372036854775808")},
+		{NewFloat(-2.9), NewInt(-2)},
+		{NewBool(true), NewInt(1)},
+		{NewNull(), NewString("")},
+	}
+
+	for _, tt := range tests {
+		got := NormalizeKey(tt.key)
+		if got.Type() != tt.expected.Type() || got.ToString() != tt.expected.ToString() {
+			t.Errorf("NormalizeKey(%s %q) = %s %q, want %s %q",
+				tt.key.TypeString(), tt.key.ToString(), got.TypeString(), got.ToString(), tt.expected.TypeString(), tt.expected.ToString())
+		}
+	}
+
+	arr := NewEmptyArray()
+	arr.Set(NewString("5"), NewString("a"))
+	arr.Set(NewInt(5), NewString("b"))
+	arr.Set(NewFloat(5.5), NewString("c"))
+	if arr.Len() != 1 {
+		t.Errorf("Expected \"5\", 5 and 5.5 to be the same key, got %s", arr)
+	}
+}
+
+func TestArrayInsertionOrderWithMixedKeys(t *testing.T) {
+	arr := NewEmptyArray()
+	arr.Set(NewString("b"), NewInt(1))
+	arr.Set(NewInt(10), NewInt(2))
+	arr.Set(NewString("a"), NewInt(3))
+	arr.Set(NewInt(-3), NewInt(4))
+
+	// Updating keeps the position, unset and re-adding moves to the end
+	arr.Set(NewString("b"), NewInt(5))
+	arr.Unset(NewInt(10))
+	arr.Set(NewInt(10), NewInt(6))
+
+	if keys := orderedKeys(arr); keys != "b a -3 10" {
+		t.Errorf("Expected keys in order b a -3 10, got %s", keys)
+	}
+	if v, _ := arr.Get(NewString("b")); v.ToInt() != 5 {
+		t.Errorf("Expected updated value 5, got %v", v)
+	}
+}
+
+func TestArrayNextIndex(t *testing.T) {
+	arr := NewEmptyArray()
+	arr.Set(NewInt(-5), NewString("a"))
+	arr.Append(NewString("b"))
+	if !arr.HasKey(NewInt(-4)) {
+		t.Errorf("Expected append after key -5 to use -4, got %s", arr)
+	}
+
+	arr = NewArrayFromSlice([]*Value{NewInt(0), NewInt(1), NewInt(2)})
+	arr.Unset(NewInt(2))
+	arr.Append(NewInt(3))
+	if !arr.HasKey(NewInt(3)) || arr.HasKey(NewInt(2)) {
+		t.Errorf("Unset keys should not be reused by append, got %s", arr)
+	}
+
+	arr.Pop()
+	if arr.NextIndex() != 3 {
+		t.Errorf("Expected array_pop to release the last key, next index is %d", arr.NextIndex())
+	}
+
+	arr = NewEmptyArray()
+	arr.Set(NewString("x"), NewInt(1))
+	if arr.NextIndex() != 0 {
+		t.Errorf("Expected next index 0 without integer keys, got %d", arr.NextIndex())
+	}
+}
+
+func TestArrayUnsetCompactsHoles(t *testing.T) {
+	arr := NewEmptyArray()
+	for i := int64(0); i < 100; i++ {
+		arr.Set(NewString(fmt.Sprintf("k%d", i)), NewInt(i))
+	}
+	for i := int64(0); i < 99; i++ {
+		arr.Unset(NewString(fmt.Sprintf("k%d", i)))
+	}
+
+	if len(arr.data.buckets) > 16 {
+		t.Errorf("Expected deleted buckets to be compacted, have %d buckets for %d elements", len(arr.data.buckets), arr.Len())
+	}
+	if v, ok := arr.Get(NewString("k99")); !ok || v.ToInt() != 99 {
+		t.Errorf("Expected k99 to survive compaction, got %v", v)
+	}
+}
+
+func TestArrayShiftUnshiftReindex(t *testing.T) {
+	arr := NewEmptyArray()
+	arr.Set(NewInt(5), NewString("a"))
+	arr.Set(NewString("x"), NewString("b"))
+	arr.Set(NewInt(9), NewString("c"))
+
+	arr.Unshift(NewString("z"))
+	if keys := orderedKeys(arr); keys != "0 1 x 2" {
+		t.Errorf("Expected keys 0 1 x 2 after unshift, got %s", keys)
+	}
+	if v, _ := arr.Get(NewInt(0)); v.ToString() != "z" {
+		t.Errorf("Expected unshifted value at key 0, got %v", v)
+	}
+
+	arr.Shift()
+	if keys := orderedKeys(arr); keys != "0 x 1" {
+		t.Errorf("Expected keys 0 x 1 after shift, got %s", keys)
+	}
+	if v, _ := arr.Get(NewInt(1)); v.ToString() != "c" {
+		t.Errorf("Expected c at key 1 after shift, got %v", v)
+	}
+
+	arr.Append(NewString("d"))
+	if !arr.HasKey(NewInt(2)) {
+		t.Errorf("Expected append after reindexing to use key 2, got %s", arr)
+	}
+}
+
+func TestNewArrayFromMapOrder(t *testing.T) {
+	arr := NewArrayFromMap(map[interface{}]*Value{
+		"b":      NewInt(1),
+		int64(2): NewInt(2),
+		"a":      NewInt(3),
+		"1":      NewInt(4),
+	})
+
+	if keys := orderedKeys(arr); keys != "1 2 a b" {
+		t.Errorf("Expected keys 1 2 a b, got %s", keys)
+	}
+}
+
+// orderedKeys returns the keys of an array in iteration order
+func orderedKeys(arr *Array) string {
+	var keys []string
+	arr.Each(func(key, value *Value) bool {
+		keys = append(keys, key.ToString())
+		return true
+	})
+	return strings.Join(keys, " ")
+}
@@ -0,0 +1,302 @@
+package types
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// Comparison
+// ============================================================================
+
+// Compare compares two values the way PHP 8's loose comparison operators
+// (==, <, <=>) do, returning -1, 0 or 1:
+//
+//   - null and bools against anything compare as bools, except null
+//     against a string, which compares as the empty string
+//   - numbers compare numerically, with numeric strings ("1e3", " 42")
+//     compared as the numbers they stand for; a number and a non-numeric
+//     string compare as strings, so 0 == "a" is false
+//   - other strings compare byte-wise
+//   - arrays compare by size, then element by element for the keys of the
+//     first one; arrays are greater than any other value but null and bools
+//   - objects equal themselves; objects of the same class compare their
+//     properties like arrays, other objects are uncomparable
+//
+// Uncomparable values (arrays with different keys, objects of different
+// classes, NaN) compare as 1 whichever side they are on, so that neither
+// a < b nor b < a holds. Objects are not converted to strings here: one
+// compared with a string is greater, callers handle __toString() first.
+func Compare(a, b *Value) int {
+	return compareValues(a.Deref(), b.Deref())
+}
+
+// LooseEquals reports whether two values are equal with PHP's ==
+func LooseEquals(a, b *Value) bool {
+	return Compare(a, b) == 0
+}
+
+// StrictEquals reports whether two values are identical with PHP's ===:
+// values of the same type and value, arrays with the same keys in the same
+// order and identical values, and the same object or resource
+func StrictEquals(a, b *Value) bool {
+	a, b = a.Deref(), b.Deref()
+	at, bt := a.Type(), b.Type()
+	if at == TypeUndef {
+		at = TypeNull
+	}
+	if bt == TypeUndef {
+		bt = TypeNull
+	}
+	if at != bt {
+		return false
+	}
+
+	switch at {
+	case TypeNull:
+		return true
+	case TypeBool, TypeInt:
+		return a.ToInt() == b.ToInt()
+	case TypeFloat:
+		return a.ToFloat() == b.ToFloat()
+	case TypeString:
+		return a.ToString() == b.ToString()
+	case TypeArray:
+		return identicalArrays(a.ToArray(), b.ToArray())
+	case TypeObject:
+		return a.ToObject() == b.ToObject()
+	case TypeResource:
+		return a.ToResource() == b.ToResource()
+	}
+	return false
+}
+
+// identicalArrays reports whether two arrays have the same keys in the
+// same order with identical values
+func identicalArrays(a, b *Array) bool {
+	if a == b {
+		return true
+	}
+	if a.Len() != b.Len() {
+		return false
+	}
+	var keys, values []*Value
+	b.Each(func(key, value *Value) bool {
+		keys = append(keys, key)
+		values = append(values, value)
+		return true
+	})
+	i, identical := 0, true
+	a.Each(func(key, value *Value) bool {
+		identical = StrictEquals(key, keys[i]) && StrictEquals(value, values[i])
+		i++
+		return identical
+	})
+	return identical
+}
+
+func compareValues(a, b *Value) int {
+	at, bt := a.Type(), b.Type()
+	if at == TypeUndef {
+		at = TypeNull
+	}
+	if bt == TypeUndef {
+		bt = TypeNull
+	}
+
+	switch {
+	case at == TypeNull && bt == TypeNull:
+		return 0
+	case at == TypeNull && bt == TypeString:
+		return compareStrings("", b.ToString())
+	case at == TypeString && bt == TypeNull:
+		return compareStrings(a.ToString(), "")
+	case at == TypeObject && bt == TypeObject:
+		return compareObjects(a.ToObject(), b.ToObject())
+	case at == TypeBool || bt == TypeBool:
+		return compareBools(a.ToBool(), b.ToBool())
+	case at == TypeObject:
+		return compareObjectTo(b, true)
+	case bt == TypeObject:
+		return compareObjectTo(a, false)
+	case at == TypeNull || bt == TypeNull:
+		return compareBools(a.ToBool(), b.ToBool())
+	case at == TypeArray && bt == TypeArray:
+		return compareArrays(a.ToArray(), b.ToArray())
+	case at == TypeArray:
+		return 1
+	case bt == TypeArray:
+		return -1
+	case at == TypeString && bt == TypeString:
+		return compareNumericStrings(a.ToString(), b.ToString())
+	case at == TypeString:
+		return -compareNumberToString(b, a.ToString())
+	case bt == TypeString:
+		return compareNumberToString(a, b.ToString())
+	}
+	return compareNumbers(numberOf(a), numberOf(b))
+}
+
+// compareObjectTo compares an object with a value other than an object, a
+// bool or null, the object being on the left if objectLeft is set: like
+// PHP, the object converts to 1 against a number and is greater than
+// strings, arrays and resources
+func compareObjectTo(other *Value, objectLeft bool) int {
+	result := 1
+	if other.Type() == TypeInt || other.Type() == TypeFloat {
+		result = compareNumbers(NewInt(1), other)
+	}
+	if !objectLeft {
+		return -result
+	}
+	return result
+}
+
+// numberOf returns the number an int, float or resource compares as
+func numberOf(v *Value) *Value {
+	if v.Type() == TypeResource {
+		return NewInt(int64(v.ToResource().ID()))
+	}
+	return v
+}
+
+// compareNumbers compares two ints or floats; NaN is uncomparable
+func compareNumbers(a, b *Value) int {
+	if a.Type() == TypeInt && b.Type() == TypeInt {
+		x, y := a.ToInt(), b.ToInt()
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	x, y := a.ToFloat(), b.ToFloat()
+	switch {
+	case x == y:
+		return 0
+	case x < y:
+		return -1
+	}
+	return 1
+}
+
+// compareNumberToString compares a number with a string: numerically if
+// the string is numeric, as strings otherwise
+func compareNumberToString(n *Value, s string) int {
+	n = numberOf(n)
+	if number, kind := ParseNumeric(s); kind == Numeric {
+		return compareNumbers(n, number)
+	}
+	if n.Type() == TypeInt {
+		return compareStrings(strconv.FormatInt(n.ToInt(), 10), s)
+	}
+	return compareStrings(n.ToString(), s)
+}
+
+// compareNumericStrings compares two strings numerically if both are
+// numeric, byte-wise otherwise
+func compareNumericStrings(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if x, kind := ParseNumeric(a); kind == Numeric {
+		if y, kind := ParseNumeric(b); kind == Numeric {
+			return compareNumbers(x, y)
+		}
+	}
+	return compareStrings(a, b)
+}
+
+func compareStrings(a, b string) int {
+	return strings.Compare(a, b)
+}
+
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
+
+// compareArrays compares arrays by size, then element by element for the
+// keys of a; arrays whose keys differ are uncomparable
+func compareArrays(a, b *Array) int {
+	if a == b {
+		return 0
+	}
+	if a.Len() != b.Len() {
+		return compareBools(a.Len() > b.Len(), a.Len() < b.Len())
+	}
+	result := 0
+	a.Each(func(key, val *Value) bool {
+		other, ok := b.Get(key)
+		if !ok {
+			result = 1
+			return false
+		}
+		result = Compare(val, other)
+		return result == 0
+	})
+	return result
+}
+
+// compareObjects compares two objects: the same object is equal to
+// itself, objects of the same class compare their properties like arrays
+// (enum cases and objects of other classes are uncomparable)
+func compareObjects(a, b *Object) int {
+	if a == b {
+		return 0
+	}
+	if a.ClassName != b.ClassName || (a.ClassEntry != nil && a.ClassEntry.IsEnum) {
+		return 1
+	}
+	if len(a.Properties) != len(b.Properties) {
+		return compareBools(len(a.Properties) > len(b.Properties), len(a.Properties) < len(b.Properties))
+	}
+	names := make([]string, 0, len(a.Properties))
+	for name := range a.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		other, ok := b.Properties[name]
+		if !ok {
+			return 1
+		}
+		if result := Compare(a.Properties[name].Value, other.Value); result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+// ============================================================================
+// Arithmetic Operands
+// ============================================================================
+
+// ToNumber converts an operand of an arithmetic operator to a number the
+// way PHP 8 does: ints and floats are kept, null and bools become ints
+// and numeric strings the number they stand for. Leading-numeric strings
+// ("12abc") convert to their leading number too, which PHP reports with a
+// warning: the kind of string is returned for callers to do so. It
+// reports false for the operands arithmetic rejects with a TypeError:
+// non-numeric strings, arrays, objects and resources.
+func ToNumber(v *Value) (*Value, NumericKind, bool) {
+	v = v.Deref()
+	switch v.Type() {
+	case TypeInt, TypeFloat:
+		return v, Numeric, true
+	case TypeUndef, TypeNull, TypeBool:
+		return NewInt(v.ToInt()), Numeric, true
+	case TypeString:
+		number, kind := ParseNumeric(v.ToString())
+		return number, kind, kind != NotNumeric
+	}
+	return nil, NotNumeric, false
+}
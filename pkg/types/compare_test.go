@@ -0,0 +1,168 @@
+package types
+
+import (
+	"math"
+	"testing"
+)
+
+// ============================================================================
+// Comparison Tests
+// ============================================================================
+
+func list(values ...*Value) *Value {
+	return NewArray(NewArrayFromSlice(values))
+}
+
+func assoc(pairs ...interface{}) *Value {
+	arr := NewEmptyArray()
+	for i := 0; i < len(pairs); i += 2 {
+		arr.Set(NewString(pairs[i].(string)), pairs[i+1].(*Value))
+	}
+	return NewArray(arr)
+}
+
+func point(x, y int64) *Value {
+	obj := NewObjectInstance("Point")
+	obj.Properties["x"] = &Property{Value: NewInt(x), Visibility: VisibilityPublic}
+	obj.Properties["y"] = &Property{Value: NewInt(y), Visibility: VisibilityPublic}
+	return NewObject(obj)
+}
+
+func TestCompareValues(t *testing.T) {
+	obj := point(1, 2)
+	tests := []struct {
+		name string
+		a, b *Value
+		want int
+	}{
+		// Numbers
+		{"ints", NewInt(1), NewInt(2), -1},
+		{"int float", NewInt(2), NewFloat(1.5), 1},
+		{"equal int float", NewInt(1), NewFloat(1.0), 0},
+		{"NaN", NewFloat(math.NaN()), NewFloat(math.NaN()), 1},
+
+		// Numbers and strings
+		{"int numeric string", NewInt(42), NewString("42"), 0},
+		{"int numeric string with whitespace", NewInt(42), NewString(" 42 "), 0},
+		{"int exponent string", NewInt(1000), NewString("1e3"), 0},
+		{"zero non-numeric string", NewInt(0), NewString("a"), -1},
+		{"non-numeric string zero", NewString("a"), NewInt(0), 1},
+		{"int leading-numeric string", NewInt(42), NewString("42abc"), -1},
+		{"float numeric string", NewFloat(1.5), NewString("1.50"), 0},
+
+		// Strings
+		{"numeric strings", NewString("10"), NewString("9"), 1},
+		{"numeric strings different forms", NewString("1e1"), NewString("10"), 0},
+		{"hex strings", NewString("0x1A"), NewString("26"), -1},
+		{"non-numeric strings", NewString("abc"), NewString("abd"), -1},
+		{"numeric and non-numeric strings", NewString("10"), NewString("9a"), -1},
+
+		// Null and bools
+		{"null empty string", NewNull(), NewString(""), 0},
+		{"null string", NewNull(), NewString("a"), -1},
+		{"null zero string", NewNull(), NewString("0"), -1},
+		{"null zero", NewNull(), NewInt(0), 0},
+		{"null negative", NewNull(), NewInt(-5), -1},
+		{"null empty array", NewNull(), list(), 0},
+		{"null false", NewNull(), NewBool(false), 0},
+		{"true string", NewBool(true), NewString("a"), 0},
+		{"false zero string", NewBool(false), NewString("0"), 0},
+		{"true empty array", NewBool(true), list(), 1},
+		{"true object", NewBool(true), obj, 0},
+		{"null object", NewNull(), obj, -1},
+
+		// Arrays
+		{"arrays by size", list(NewInt(5)), list(NewInt(1), NewInt(2)), -1},
+		{"arrays by element", list(NewInt(1), NewInt(3)), list(NewInt(1), NewInt(2)), 1},
+		{"arrays loosely equal", list(NewInt(1), NewString("2")), list(NewString("1"), NewInt(2)), 0},
+		{"arrays in another order", assoc("a", NewInt(1), "b", NewInt(2)), assoc("b", NewInt(2), "a", NewInt(1)), 0},
+		{"arrays with other keys", assoc("a", NewInt(1)), assoc("b", NewInt(1)), 1},
+		{"arrays with other keys reversed", assoc("b", NewInt(1)), assoc("a", NewInt(1)), 1},
+		{"array int", list(), NewInt(5), 1},
+		{"string array", NewString("a"), list(), -1},
+
+		// Objects
+		{"same object", obj, obj, 0},
+		{"equal objects", point(1, 2), point(1, 2), 0},
+		{"objects by property", point(1, 2), point(1, 3), -1},
+		{"objects of other classes", point(1, 2), NewObject(NewObjectInstance("Other")), 1},
+		{"object int", obj, NewInt(1), 0},
+		{"int object", NewInt(2), obj, 1},
+		{"object string", obj, NewString("a"), 1},
+		{"string object", NewString("a"), obj, -1},
+	}
+
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: Compare(%v, %v) = %d, want %d", tt.name, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestStrictEquals(t *testing.T) {
+	obj := point(1, 2)
+	tests := []struct {
+		name string
+		a, b *Value
+		want bool
+	}{
+		{"ints", NewInt(1), NewInt(1), true},
+		{"int float", NewInt(1), NewFloat(1), false},
+		{"int string", NewInt(1), NewString("1"), false},
+		{"null undef", NewNull(), NewUndef(), true},
+		{"numeric strings", NewString("1e1"), NewString("10"), false},
+		{"equal arrays", list(NewInt(1), NewInt(2)), list(NewInt(1), NewInt(2)), true},
+		{"arrays with loose elements", list(NewInt(1)), list(NewString("1")), false},
+		{"arrays in another order", assoc("a", NewInt(1), "b", NewInt(2)), assoc("b", NewInt(2), "a", NewInt(1)), false},
+		{"same object", obj, obj, true},
+		{"equal objects", point(1, 2), point(1, 2), false},
+		{"reference", NewReference(NewInt(1)), NewInt(1), true},
+	}
+
+	for _, tt := range tests {
+		if got := StrictEquals(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: StrictEquals(%v, %v) = %v, want %v", tt.name, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLooseEquals_Arrays(t *testing.T) {
+	a := assoc("a", NewInt(1), "b", NewInt(2))
+	b := assoc("b", NewString("2"), "a", NewBool(true))
+	if !LooseEquals(a, b) {
+		t.Error("Expected arrays with loosely equal elements in another order to be equal")
+	}
+	if LooseEquals(list(NewInt(1)), list(NewInt(1), NewInt(1))) {
+		t.Error("Expected arrays of different sizes to differ")
+	}
+}
+
+func TestToNumber(t *testing.T) {
+	tests := []struct {
+		input *Value
+		want  *Value
+		kind  NumericKind
+		ok    bool
+	}{
+		{NewInt(5), NewInt(5), Numeric, true},
+		{NewFloat(1.5), NewFloat(1.5), Numeric, true},
+		{NewNull(), NewInt(0), Numeric, true},
+		{NewBool(true), NewInt(1), Numeric, true},
+		{NewString(" 12 "), NewInt(12), Numeric, true},
+		{NewString("12abc"), NewInt(12), LeadingNumeric, true},
+		{NewString("abc"), nil, NotNumeric, false},
+		{list(), nil, NotNumeric, false},
+		{point(1, 2), nil, NotNumeric, false},
+	}
+
+	for _, tt := range tests {
+		got, kind, ok := ToNumber(tt.input)
+		if ok != tt.ok || kind != tt.kind {
+			t.Errorf("ToNumber(%v) = %v, %v, want %v, %v", tt.input, kind, ok, tt.kind, tt.ok)
+			continue
+		}
+		if ok && !got.Identical(tt.want) {
+			t.Errorf("ToNumber(%v) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -196,6 +196,58 @@ func TestEnum_CasesMethod(t *testing.T) {
 	if len(cases) != 3 {
 		t.Errorf("Expected 3 cases from cases(), got %d", len(cases))
 	}
+
+	// Cases are listed in declaration order
+	if joinStrings(cases, ",") != "Pending,Active,Inactive" {
+		t.Errorf("Expected cases in declaration order, got %v", cases)
+	}
+}
+
+func TestEnum_CaseObjects(t *testing.T) {
+	enum := NewEnumEntry("Suit", "string")
+	enum.AddCase("Hearts", NewString("H"))
+	enum.AddCase("Spades", NewString("S"))
+
+	hearts, ok := enum.EnumCase("Hearts")
+	if !ok {
+		t.Fatal("Expected case Hearts")
+	}
+	again, _ := enum.EnumCase("Hearts")
+	if !hearts.Identical(again) {
+		t.Error("Expected case objects to be singletons")
+	}
+	spades, _ := enum.EnumCase("Spades")
+	if hearts.Identical(spades) {
+		t.Error("Expected different cases to be different objects")
+	}
+
+	obj := hearts.ToObject()
+	if name, _ := obj.GetProperty("name", nil); name.ToString() != "Hearts" {
+		t.Errorf("Expected name Hearts, got %v", name)
+	}
+	if value, _ := obj.GetProperty("value", nil); value.ToString() != "H" {
+		t.Errorf("Expected value H, got %v", value)
+	}
+	if obj.SetProperty("name", NewString("Clubs"), enum) {
+		t.Error("Expected case properties to be readonly")
+	}
+
+	if found, ok := enum.EnumCaseFor(NewString("S")); !ok || !found.Identical(spades) {
+		t.Errorf("Expected EnumCaseFor(S) to be Spades, got %v", found)
+	}
+	if _, ok := enum.EnumCaseFor(NewString("X")); ok {
+		t.Error("Expected no case for X")
+	}
+	if _, ok := enum.EnumCase("Clubs"); ok {
+		t.Error("Expected no case Clubs")
+	}
+
+	pure := NewEnumEntry("Status", "")
+	pure.AddCase("Active", nil)
+	active, _ := pure.EnumCase("Active")
+	if _, exists := active.ToObject().Properties["value"]; exists {
+		t.Error("Expected pure enum cases to have no value property")
+	}
 }
 
 func TestEnum_FromMethod(t *testing.T) {
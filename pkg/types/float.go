@@ -0,0 +1,146 @@
+package types
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ============================================================================
+// Float Formatting
+// ============================================================================
+
+// Float precisions of the conversions to string: the precision ini
+// setting applies to the string conversion of floats (echo, string
+// interpolation, print_r()) and serialize_precision to the functions
+// that must preserve the value (var_dump(), var_export(), serialize(),
+// json_encode()). -1 selects the shortest representation that reads back
+// as the same float.
+const (
+	DefaultPrecision          = 14
+	DefaultSerializePrecision = -1
+)
+
+var (
+	floatPrecision     atomic.Int64
+	serializePrecision atomic.Int64
+)
+
+func init() {
+	floatPrecision.Store(DefaultPrecision)
+	serializePrecision.Store(DefaultSerializePrecision)
+}
+
+// Precision returns the precision of the string conversion of floats
+func Precision() int {
+	return int(floatPrecision.Load())
+}
+
+// SetPrecision changes the precision of the string conversion of floats,
+// the precision ini setting
+func SetPrecision(p int) {
+	floatPrecision.Store(int64(p))
+}
+
+// SerializePrecision returns the precision of the functions preserving
+// floats
+func SerializePrecision() int {
+	return int(serializePrecision.Load())
+}
+
+// SetSerializePrecision changes the precision of the functions preserving
+// floats, the serialize_precision ini setting
+func SetSerializePrecision(p int) {
+	serializePrecision.Store(int64(p))
+}
+
+// FormatFloat formats a float the way PHP's %G-like conversion does:
+// precision significant digits (the shortest representation for -1, at
+// least one digit for 0), switching to exponential notation for exponents
+// below -4 or beyond the precision, as in 1.0E+25. Negative zero keeps
+// its sign ("-0"), infinities and NaN are "INF", "-INF" and "NAN".
+func FormatFloat(f float64, precision int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NAN"
+	case math.IsInf(f, 1):
+		return "INF"
+	case math.IsInf(f, -1):
+		return "-INF"
+	}
+
+	if precision == 0 {
+		precision = 1
+	}
+	ndigit := precision
+	if precision < 0 {
+		ndigit = 17
+	}
+
+	// Decompose into significant digits and the decimal point position
+	var mantissa string
+	if precision < 0 {
+		mantissa = strconv.FormatFloat(math.Abs(f), 'e', -1, 64)
+	} else {
+		mantissa = strconv.FormatFloat(math.Abs(f), 'e', precision-1, 64)
+	}
+	mant, exp, _ := strings.Cut(mantissa, "e")
+	digits := strings.TrimRight(strings.Replace(mant, ".", "", 1), "0")
+	if digits == "" {
+		digits = "0"
+	}
+	e, _ := strconv.Atoi(exp)
+	decpt := e + 1
+	if f == 0 {
+		decpt = 1
+	}
+
+	var out strings.Builder
+	if math.Signbit(f) {
+		out.WriteByte('-')
+	}
+
+	if decpt < -3 || decpt > ndigit {
+		// Exponential format, always with a fractional digit
+		out.WriteByte(digits[0])
+		out.WriteByte('.')
+		if len(digits) > 1 {
+			out.WriteString(digits[1:])
+		} else {
+			out.WriteByte('0')
+		}
+		out.WriteByte('E')
+		if decpt-1 < 0 {
+			out.WriteByte('-')
+		} else {
+			out.WriteByte('+')
+		}
+		out.WriteString(strconv.Itoa(absInt(decpt - 1)))
+		return out.String()
+	}
+
+	if decpt <= 0 {
+		out.WriteString("0.")
+		out.WriteString(strings.Repeat("0", -decpt))
+		out.WriteString(digits)
+		return out.String()
+	}
+
+	if len(digits) <= decpt {
+		out.WriteString(digits)
+		out.WriteString(strings.Repeat("0", decpt-len(digits)))
+		return out.String()
+	}
+	out.WriteString(digits[:decpt])
+	out.WriteByte('.')
+	out.WriteString(digits[decpt:])
+	return out.String()
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
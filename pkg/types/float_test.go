@@ -0,0 +1,90 @@
+package types
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFormatFloat(t *testing.T) {
+	a, b := 0.1, 0.2
+	tests := []struct {
+		f         float64
+		precision int
+		expected  string
+	}{
+		{1, DefaultSerializePrecision, "1"},
+		{0.1, DefaultSerializePrecision, "0.1"},
+		{a + b, DefaultSerializePrecision, "0.30000000000000004"},
+		{a + b, DefaultPrecision, "0.3"},
+		{-1.5, DefaultSerializePrecision, "-1.5"},
+		{math.Copysign(0, -1), DefaultSerializePrecision, "-0"},
+		{1e25, DefaultSerializePrecision, "1.0E+25"},
+		{1.5e-7, DefaultSerializePrecision, "1.5E-7"},
+		{0.0001, DefaultSerializePrecision, "0.0001"},
+		{1e15, DefaultPrecision, "1.0E+15"},
+		{123456789012345678, DefaultSerializePrecision, "1.2345678901234568E+17"},
+		{1e17, DefaultSerializePrecision, "1.0E+17"},
+		{1e16, DefaultSerializePrecision, "10000000000000000"},
+		{100, DefaultPrecision, "100"},
+		{math.Pi, DefaultPrecision, "3.1415926535898"},
+		{math.Inf(1), DefaultSerializePrecision, "INF"},
+		{math.Inf(-1), DefaultSerializePrecision, "-INF"},
+		{math.NaN(), DefaultSerializePrecision, "NAN"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatFloat(tt.f, tt.precision); got != tt.expected {
+			t.Errorf("FormatFloat(%v, %d) = %q, want %q", tt.f, tt.precision, got, tt.expected)
+		}
+	}
+}
+
+// TestFloatToString checks the string conversion of floats against the
+// output of echo in php-src (Zend/tests, ext/standard/tests/general_functions)
+func TestFloatToString(t *testing.T) {
+	a, b := 0.1, 0.2
+	tests := []struct {
+		f        float64
+		expected string
+	}{
+		{a + b, "0.3"},
+		{1.0, "1"},
+		{-1.5, "-1.5"},
+		{math.Copysign(0, -1), "-0"},
+		{0, "0"},
+		{1e14, "1.0E+14"},
+		{123456789012345.678, "1.2345678901235E+14"},
+		{1e100, "1.0E+100"},
+		{0.0001, "0.0001"},
+		{0.00001, "1.0E-5"},
+		{float64(math.MaxInt64) + 1, "9.2233720368548E+18"},
+		{1 / 3.0, "0.33333333333333"},
+		{math.Inf(1), "INF"},
+		{math.Inf(-1), "-INF"},
+		{math.NaN(), "NAN"},
+	}
+
+	for _, tt := range tests {
+		if got := NewFloat(tt.f).ToString(); got != tt.expected {
+			t.Errorf("(string) %v = %q, want %q", tt.f, got, tt.expected)
+		}
+	}
+}
+
+func TestFloatToString_Precision(t *testing.T) {
+	defer SetPrecision(DefaultPrecision)
+
+	SetPrecision(17)
+	if got := NewFloat(0.1).ToString(); got != "0.10000000000000001" {
+		t.Errorf("(string) 0.1 with precision 17 = %q", got)
+	}
+	SetPrecision(-1)
+	a, b := 0.1, 0.2
+	if got := NewFloat(a + b).ToString(); got != "0.30000000000000004" {
+		t.Errorf("(string) 0.1 + 0.2 with precision -1 = %q", got)
+	}
+	SetPrecision(0)
+	if got := NewFloat(1.5).ToString(); got != "2" {
+		t.Errorf("(string) 1.5 with precision 0 = %q", got)
+	}
+}
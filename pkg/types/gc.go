@@ -0,0 +1,382 @@
+package types
+
+import (
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// ============================================================================
+// Reference Counting
+// ============================================================================
+
+// Objects count their holders: the variable slots, references, properties
+// and array elements storing them. Go reclaims the memory, so the count
+// serves the destructors and the cycle collector: an object whose count
+// drops to zero is released, so its destructor can run, and one whose
+// count is decremented without reaching zero may be the root of a garbage
+// cycle and is buffered for the next collection, as in PHP. Holders the
+// engine keeps outside those slots are not counted, so a count is a hint
+// and the engine confirms by reachability that an object is garbage.
+
+// AddRef records a new holder of the object
+func (o *Object) AddRef() {
+	o.refcount.Add(1)
+}
+
+// DelRef records a dropped holder and returns the number left. An object
+// still held is buffered as a possible root of a garbage cycle, one no
+// longer held is released.
+func (o *Object) DelRef() int32 {
+	n := o.refcount.Add(-1)
+	if n < 0 {
+		// A holder that was not counted
+		o.refcount.Store(0)
+		n = 0
+	}
+	if o.roots != nil {
+		if n > 0 {
+			o.roots.add(o)
+		} else {
+			o.roots.Release(o)
+		}
+	}
+	return n
+}
+
+// RefCount returns the number of counted holders of the object
+func (o *Object) RefCount() int32 {
+	return o.refcount.Load()
+}
+
+// TrackRoots makes the object buffer itself in roots when it becomes a
+// possible cycle root
+func (o *Object) TrackRoots(roots *RootBuffer) {
+	o.roots = roots
+}
+
+// AddRef records a new holder of the object a value holds, if any
+func AddRef(v *Value) {
+	if v != nil && v.typ == TypeObject {
+		v.data.(*Object).AddRef()
+	}
+}
+
+// DelRef records a dropped holder of the object a value holds. It returns
+// the object if no counted holder is left, else nil. Dropping an array
+// whose storage is not shared drops its elements too.
+func DelRef(v *Value) *Object {
+	if v == nil {
+		return nil
+	}
+	switch v.typ {
+	case TypeObject:
+		obj := v.data.(*Object)
+		if obj.DelRef() == 0 {
+			return obj
+		}
+	case TypeArray:
+		if arr := v.data.(*Array); arr.RefCount() == 1 {
+			arr.Each(func(_, element *Value) bool {
+				DelRef(element)
+				return true
+			})
+		}
+	}
+	return nil
+}
+
+// RefCount returns the number of handles sharing the array's storage
+func (a *Array) RefCount() int32 {
+	if a == nil || a.data == nil {
+		return 0
+	}
+	return a.data.refcount.Load()
+}
+
+// ============================================================================
+// Root Buffer
+// ============================================================================
+
+// RootBuffer holds the possible roots of garbage cycles until the next
+// collection, and the released objects until the engine destroys them.
+// Once full, further roots are dropped.
+type RootBuffer struct {
+	mu       sync.Mutex
+	roots    map[*Object]struct{}
+	limit    int
+	released []*Object
+	pending  atomic.Bool // Whether released holds objects
+}
+
+// NewRootBuffer creates a buffer of at most limit roots (0 for no limit)
+func NewRootBuffer(limit int) *RootBuffer {
+	return &RootBuffer{roots: make(map[*Object]struct{}), limit: limit}
+}
+
+// add buffers a possible root
+func (b *RootBuffer) add(o *Object) {
+	b.mu.Lock()
+	if b.limit == 0 || len(b.roots) < b.limit {
+		b.roots[o] = struct{}{}
+	}
+	b.mu.Unlock()
+}
+
+// Len returns the number of buffered roots
+func (b *RootBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.roots)
+}
+
+// Full reports whether the buffer drops new roots
+func (b *RootBuffer) Full() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.limit > 0 && len(b.roots) >= b.limit
+}
+
+// Release records an object that may no longer be held
+func (b *RootBuffer) Release(o *Object) {
+	b.mu.Lock()
+	b.released = append(b.released, o)
+	b.pending.Store(true)
+	b.mu.Unlock()
+}
+
+// HasReleased reports whether objects were released since the last
+// TakeReleased
+func (b *RootBuffer) HasReleased() bool {
+	return b.pending.Load()
+}
+
+// TakeReleased returns the released objects in release order and forgets
+// them
+func (b *RootBuffer) TakeReleased() []*Object {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	released := b.released
+	b.released = nil
+	b.pending.Store(false)
+	return released
+}
+
+// Take empties the buffer, returning the roots it held
+func (b *RootBuffer) Take() []*Object {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	roots := make([]*Object, 0, len(b.roots))
+	for o := range b.roots {
+		roots = append(roots, o)
+	}
+	clear(b.roots)
+	return roots
+}
+
+// ============================================================================
+// Reachability
+// ============================================================================
+
+// Marker marks the objects reachable from a set of roots: through values,
+// references, array elements, properties and the engine payloads of
+// objects. Go structures are walked by reflection, so roots can be any
+// engine state holding PHP values.
+type Marker struct {
+	objects []*Object
+	marked  map[*Object]bool
+	arrays  map[*arrayData]bool
+	seen    map[seenPointer]bool
+}
+
+// seenPointer identifies a Go pointer walked by the marker
+type seenPointer struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// NewMarker creates a marker with nothing marked
+func NewMarker() *Marker {
+	return &Marker{
+		marked: make(map[*Object]bool),
+		arrays: make(map[*arrayData]bool),
+		seen:   make(map[seenPointer]bool),
+	}
+}
+
+// Marked reports whether an object is reachable from the marked roots
+func (m *Marker) Marked(o *Object) bool {
+	return m.marked[o]
+}
+
+// Objects returns the marked objects in the order they were reached
+func (m *Marker) Objects() []*Object {
+	return m.objects
+}
+
+// MarkValue marks what a value holds
+func (m *Marker) MarkValue(v *Value) {
+	for v != nil {
+		switch v.typ {
+		case TypeReference:
+			v, _ = v.data.(*Value)
+			continue
+		case TypeObject:
+			m.MarkObject(v.data.(*Object))
+		case TypeArray:
+			m.MarkArray(v.data.(*Array))
+		}
+		return
+	}
+}
+
+// MarkArray marks the elements of an array
+func (m *Marker) MarkArray(a *Array) {
+	if a == nil || a.data == nil || m.arrays[a.data] {
+		return
+	}
+	m.arrays[a.data] = true
+	if a.data.packed {
+		for _, v := range a.data.packedData {
+			m.MarkValue(v)
+		}
+		return
+	}
+	for _, b := range a.data.buckets {
+		m.MarkValue(b.val)
+	}
+}
+
+// MarkObject marks an object with its properties and engine payload
+func (m *Marker) MarkObject(o *Object) {
+	if o == nil || m.marked[o] {
+		return
+	}
+	m.marked[o] = true
+	m.objects = append(m.objects, o)
+	for _, prop := range o.Properties {
+		if prop != nil {
+			m.MarkValue(prop.Value)
+		}
+	}
+	if o.ClassEntry != nil {
+		m.Mark(o.ClassEntry)
+	}
+	if o.Internal != nil {
+		m.Mark(o.Internal)
+	}
+}
+
+// Mark marks the PHP values reachable from any Go value
+func (m *Marker) Mark(root interface{}) {
+	if root != nil {
+		m.walk(reflect.ValueOf(root))
+	}
+}
+
+// MarkFields marks the PHP values reachable from the fields of the struct
+// ptr points to, except the named ones
+func (m *Marker) MarkFields(ptr interface{}, skip ...string) {
+	rv := reflect.ValueOf(ptr).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		if !slices.Contains(skip, rv.Type().Field(i).Name) {
+			m.walk(rv.Field(i))
+		}
+	}
+}
+
+var (
+	valueType  = reflect.TypeOf((*Value)(nil))
+	objectType = reflect.TypeOf((*Object)(nil))
+	arrayType  = reflect.TypeOf((*Array)(nil))
+)
+
+// walk marks the PHP values inside a Go value
+func (m *Marker) walk(rv reflect.Value) {
+	if !rv.IsValid() || !mayHoldValues(rv.Type()) {
+		return
+	}
+	switch rv.Type() {
+	case valueType:
+		m.MarkValue((*Value)(rv.UnsafePointer()))
+		return
+	case objectType:
+		m.MarkObject((*Object)(rv.UnsafePointer()))
+		return
+	case arrayType:
+		m.MarkArray((*Array)(rv.UnsafePointer()))
+		return
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return
+		}
+		key := seenPointer{uintptr(rv.UnsafePointer()), rv.Type()}
+		if m.seen[key] {
+			return
+		}
+		m.seen[key] = true
+		m.walk(rv.Elem())
+	case reflect.Interface:
+		m.walk(rv.Elem())
+	case reflect.Struct:
+		for i := 0; i < rv.NumField(); i++ {
+			m.walk(rv.Field(i))
+		}
+	case reflect.Slice:
+		if rv.IsNil() {
+			return
+		}
+		key := seenPointer{uintptr(rv.UnsafePointer()), rv.Type()}
+		if m.seen[key] {
+			return
+		}
+		m.seen[key] = true
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			m.walk(rv.Index(i))
+		}
+	case reflect.Map:
+		iter := rv.MapRange()
+		for iter.Next() {
+			m.walk(iter.Key())
+			m.walk(iter.Value())
+		}
+	}
+}
+
+// holdsValues caches whether the values of a Go type can reach PHP values
+var holdsValues sync.Map // reflect.Type => bool
+
+// mayHoldValues reports whether values of a type can reach PHP values.
+// Functions, channels and unsafe pointers are not walked.
+func mayHoldValues(t reflect.Type) bool {
+	if cached, ok := holdsValues.Load(t); ok {
+		return cached.(bool)
+	}
+	// A recursive type holds values if any other part does; assume it
+	// does while its parts are checked
+	holdsValues.Store(t, true)
+	result := false
+	switch t.Kind() {
+	case reflect.Interface:
+		result = true
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		result = mayHoldValues(t.Elem())
+	case reflect.Map:
+		result = mayHoldValues(t.Key()) || mayHoldValues(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if mayHoldValues(t.Field(i).Type) {
+				result = true
+				break
+			}
+		}
+	}
+	holdsValues.Store(t, result)
+	return result
+}
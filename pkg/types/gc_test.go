@@ -0,0 +1,66 @@
+package types
+
+import "testing"
+
+func TestObject_RefCount(t *testing.T) {
+	roots := NewRootBuffer(0)
+	obj := NewObjectInstance("stdClass")
+	obj.TrackRoots(roots)
+	value := NewObject(obj)
+
+	arr := NewEmptyArray()
+	arr.Set(NewString("a"), value)
+	arr.Append(value)
+	holder := NewObjectInstance("stdClass")
+	holder.AssignProperty("p", value, nil)
+	if obj.RefCount() != 3 {
+		t.Fatalf("RefCount() = %d, want 3", obj.RefCount())
+	}
+
+	arr.Unset(NewString("a"))
+	if obj.RefCount() != 2 || roots.Len() != 1 {
+		t.Errorf("after unset: RefCount() = %d, roots %d", obj.RefCount(), roots.Len())
+	}
+
+	// Separating a shared array copies its holders
+	shared := arr.Copy()
+	shared.Set(NewString("x"), NewNull())
+	if obj.RefCount() != 3 {
+		t.Errorf("after separation: RefCount() = %d", obj.RefCount())
+	}
+
+	holder.UnsetProperty("p", nil)
+	shared.Set(NewInt(0), NewNull())
+	if released := DelRef(value); released != obj {
+		t.Errorf("DelRef() = %v, want the object", released)
+	}
+	if taken := roots.Take(); len(taken) != 1 || taken[0] != obj || roots.Len() != 0 {
+		t.Errorf("Take() = %v", taken)
+	}
+}
+
+func TestMarker_Reachability(t *testing.T) {
+	a := NewObjectInstance("A")
+	b := NewObjectInstance("B")
+	c := NewObjectInstance("C")
+	a.AssignProperty("b", NewObject(b), nil)
+	b.AssignProperty("a", NewObject(a), nil)
+
+	// c is reachable through a reference in an array held by a Go structure
+	list := NewEmptyArray()
+	list.Append(NewReference(NewObject(c)))
+	root := struct {
+		values []interface{}
+	}{[]interface{}{NewArray(list)}}
+
+	m := NewMarker()
+	m.Mark(&root)
+	if !m.Marked(c) || m.Marked(a) || m.Marked(b) {
+		t.Errorf("marked a=%v b=%v c=%v", m.Marked(a), m.Marked(b), m.Marked(c))
+	}
+
+	m.MarkObject(a)
+	if !m.Marked(b) || len(m.Objects()) != 3 {
+		t.Errorf("cycle not marked: %d objects", len(m.Objects()))
+	}
+}
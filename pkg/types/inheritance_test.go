@@ -73,9 +73,46 @@ func TestInheritance_PropertyInheritance(t *testing.T) {
 		t.Error("Child should inherit protected property")
 	}
 
-	// Private should NOT be inherited
-	if _, exists := child.Properties["privateProp"]; exists {
-		t.Error("Child should NOT inherit private property")
+	// Private properties keep their slot, still private to the parent
+	if prop, exists := child.Properties["privateProp"]; !exists || prop.DeclaringClass != "Parent" {
+		t.Error("Child objects should keep the parent's private property")
+	}
+}
+
+func TestInheritance_ShadowedPrivateProperty(t *testing.T) {
+	parent := NewClassEntry("Parent")
+	parent.Properties["prop"] = &PropertyDef{
+		Name:           "prop",
+		Visibility:     VisibilityPrivate,
+		Default:        NewString("parent"),
+		DeclaringClass: "Parent",
+	}
+
+	child := NewClassEntry("Child")
+	child.Properties["prop"] = &PropertyDef{
+		Name:           "prop",
+		Visibility:     VisibilityPrivate,
+		Default:        NewString("child"),
+		DeclaringClass: "Child",
+	}
+	if err := child.InheritFrom(parent); err != nil {
+		t.Fatalf("InheritFrom failed: %v", err)
+	}
+
+	key := PrivatePropertyKey("Parent", "prop")
+	if prop, exists := child.Properties[key]; !exists || prop.Name != "prop" {
+		t.Fatalf("Child should keep the parent's private property under %q", key)
+	}
+
+	obj := NewObjectFromClass(child)
+	if value, ok := obj.GetProperty("prop", parent); !ok || value.ToString() != "parent" {
+		t.Errorf("Parent scope: got %v, want \"parent\"", value)
+	}
+	if value, ok := obj.GetProperty("prop", child); !ok || value.ToString() != "child" {
+		t.Errorf("Child scope: got %v, want \"child\"", value)
+	}
+	if _, ok := obj.GetProperty("prop", nil); ok {
+		t.Error("Private properties should not be accessible from the global scope")
 	}
 }
 
@@ -203,6 +240,47 @@ func TestInheritance_AbstractMethodMustBeImplemented(t *testing.T) {
 	}
 }
 
+func TestInheritance_ValidateAbstractMethods(t *testing.T) {
+	iface := NewInterfaceEntry("Shape")
+	iface.Methods["name"] = &MethodDef{Name: "name", Visibility: VisibilityPublic, IsAbstract: true}
+
+	parent := NewClassEntry("Base")
+	parent.IsAbstract = true
+	parent.Interfaces = []*InterfaceEntry{iface}
+	parent.Methods["area"] = &MethodDef{Name: "area", Visibility: VisibilityPublic, IsAbstract: true, DeclaringClass: "Base"}
+	parent.Methods["scale"] = &MethodDef{Name: "scale", Visibility: VisibilityPublic, IsAbstract: true, DeclaringClass: "Base"}
+	if err := parent.ValidateAbstractMethods(); err != nil {
+		t.Errorf("Expected an abstract class to be valid, got %v", err)
+	}
+
+	// Implementing some of the methods leaves the others
+	child := NewClassEntry("Square")
+	child.Methods["area"] = &MethodDef{Name: "area", Visibility: VisibilityPublic, DeclaringClass: "Square"}
+	if err := child.InheritFrom(parent); err != nil {
+		t.Fatalf("InheritFrom failed: %v", err)
+	}
+	want := "Class Square contains 2 abstract methods and must therefore be declared abstract or implement the remaining methods (Base::scale, Shape::name)"
+	if err := child.ValidateAbstractMethods(); err == nil || err.Error() != want {
+		t.Errorf("Expected %q, got %v", want, err)
+	}
+
+	// A class implementing everything, possibly through a parent, is valid
+	child.Methods["scale"] = &MethodDef{Name: "scale", Visibility: VisibilityPublic, DeclaringClass: "Square"}
+	child.Methods["name"] = &MethodDef{Name: "name", Visibility: VisibilityPublic, DeclaringClass: "Square"}
+	grandchild := NewClassEntry("SmallSquare")
+	if err := grandchild.InheritFrom(child); err != nil {
+		t.Fatalf("InheritFrom failed: %v", err)
+	}
+	for _, ce := range []*ClassEntry{child, grandchild} {
+		if ce.HasAbstractMethods() {
+			t.Errorf("Expected %s to have no unimplemented methods, got %v", ce.Name, ce.UnimplementedAbstractMethods())
+		}
+		if err := ce.ValidateAbstractMethods(); err != nil {
+			t.Errorf("Expected %s to be valid, got %v", ce.Name, err)
+		}
+	}
+}
+
 func TestInheritance_MultiLevelInheritance(t *testing.T) {
 	// Grandparent -> Parent -> Child
 	grandparent := NewClassEntry("Grandparent")
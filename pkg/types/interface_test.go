@@ -333,6 +333,21 @@ func TestInterface_AccessConstants(t *testing.T) {
 	if len(iface.Constants) != 1 {
 		t.Error("Interface should have 1 constant")
 	}
+
+	// Constants are found through the class, its parents and extended interfaces
+	child := NewClassEntry("ChildConfig")
+	child.InheritFrom(class)
+	extended := NewInterfaceEntry("ExtendedConfig")
+	extended.ParentInterfaces = []*InterfaceEntry{iface}
+	other := NewClassEntry("OtherConfig")
+	other.Interfaces = []*InterfaceEntry{extended}
+
+	for _, ce := range []*ClassEntry{class, child, other} {
+		value, exists := ce.GetStaticConstant("VERSION", false, nil)
+		if !exists || value.ToString() != "1.0.0" {
+			t.Errorf("%s::VERSION: expected 1.0.0, got %v", ce.Name, value)
+		}
+	}
 }
 
 // ============================================================================
@@ -0,0 +1,297 @@
+package types
+
+import (
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+// ============================================================================
+// Numeric Strings
+// ============================================================================
+
+// NumericKind classifies a string by how much of it is a number
+type NumericKind int
+
+const (
+	// NotNumeric strings contain no leading number ("abc", "")
+	NotNumeric NumericKind = iota
+	// LeadingNumeric strings start with a number followed by other
+	// characters ("123abc", "1.5 apples")
+	LeadingNumeric
+	// Numeric strings are a number with optional surrounding whitespace
+	// (" 42", "1e3 ", "-.5")
+	Numeric
+)
+
+// isNumericSpace reports the whitespace allowed around numeric strings
+func isNumericSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+// ParseNumeric parses a string the way PHP's arithmetic does. It returns
+// the int or float value of the (leading) number and the kind of string;
+// the value is int 0 for non-numeric strings. Integers that overflow are
+// returned as floats.
+func ParseNumeric(s string) (*Value, NumericKind) {
+	i := 0
+	for i < len(s) && isNumericSpace(s[i]) {
+		i++
+	}
+	start := i
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+
+	digits := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+		digits++
+	}
+	isFloat := false
+	if i < len(s) && s[i] == '.' {
+		j := i + 1
+		fraction := 0
+		for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			j++
+			fraction++
+		}
+		if digits > 0 || fraction > 0 {
+			i, digits, isFloat = j, digits+fraction, true
+		}
+	}
+	if digits == 0 {
+		return NewInt(0), NotNumeric
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			i, isFloat = j, true
+		}
+	}
+
+	number := s[start:i]
+	kind := Numeric
+	for j := i; j < len(s); j++ {
+		if !isNumericSpace(s[j]) {
+			kind = LeadingNumeric
+			break
+		}
+	}
+
+	if !isFloat {
+		if n, err := strconv.ParseInt(number, 10, 64); err == nil {
+			return NewInt(n), kind
+		}
+	}
+	f, _ := strconv.ParseFloat(number, 64)
+	return NewFloat(f), kind
+}
+
+// ============================================================================
+// Increment and Decrement
+// ============================================================================
+
+// Increment returns the value of ++$v. Integers overflow to float, null
+// becomes 1, numeric strings are incremented as numbers and other strings
+// alphanumerically ("a" to "b", "Az" to "Ba", "zz" to "aaa"). Booleans
+// are left unchanged. It reports false for arrays, objects and resources,
+// which cannot be incremented.
+func (v *Value) Increment() (*Value, bool) {
+	v = v.Deref()
+	switch v.Type() {
+	case TypeUndef, TypeNull:
+		return NewInt(1), true
+	case TypeBool:
+		return v, true
+	case TypeInt:
+		n := v.ToInt()
+		if n == math.MaxInt64 {
+			return NewFloat(float64(n) + 1), true
+		}
+		return NewInt(n + 1), true
+	case TypeFloat:
+		return NewFloat(v.ToFloat() + 1), true
+	case TypeString:
+		s := v.ToString()
+		if s == "" {
+			return NewString("1"), true
+		}
+		if number, kind := ParseNumeric(s); kind == Numeric {
+			return number.Increment()
+		}
+		return NewString(incrementString(s)), true
+	default:
+		return nil, false
+	}
+}
+
+// Decrement returns the value of --$v. Integers overflow to float, null
+// stays null, the empty string becomes -1 and numeric strings are
+// decremented as numbers; other strings and booleans are left unchanged.
+// It reports false for arrays, objects and resources.
+func (v *Value) Decrement() (*Value, bool) {
+	v = v.Deref()
+	switch v.Type() {
+	case TypeUndef, TypeNull:
+		return NewNull(), true
+	case TypeBool:
+		return v, true
+	case TypeInt:
+		n := v.ToInt()
+		if n == math.MinInt64 {
+			return NewFloat(float64(n) - 1), true
+		}
+		return NewInt(n - 1), true
+	case TypeFloat:
+		return NewFloat(v.ToFloat() - 1), true
+	case TypeString:
+		s := v.ToString()
+		if s == "" {
+			return NewInt(-1), true
+		}
+		if number, kind := ParseNumeric(s); kind == Numeric {
+			return number.Decrement()
+		}
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+// incrementString increments the trailing alphanumeric run of a string,
+// carrying like a counter in each character class: "a9" becomes "b0",
+// "Zz" becomes "AAa". A trailing character outside [a-zA-Z0-9] stops
+// the carry.
+func incrementString(s string) string {
+	b := []byte(s)
+	var prefix byte
+	for pos := len(b) - 1; pos >= 0; pos-- {
+		switch c := b[pos]; {
+		case c >= 'a' && c <= 'z':
+			if c != 'z' {
+				b[pos]++
+				return string(b)
+			}
+			b[pos], prefix = 'a', 'a'
+		case c >= 'A' && c <= 'Z':
+			if c != 'Z' {
+				b[pos]++
+				return string(b)
+			}
+			b[pos], prefix = 'A', 'A'
+		case c >= '0' && c <= '9':
+			if c != '9' {
+				b[pos]++
+				return string(b)
+			}
+			b[pos], prefix = '0', '1'
+		default:
+			return string(b)
+		}
+	}
+	return string(prefix) + string(b)
+}
+
+// ============================================================================
+// Integer Overflow
+// ============================================================================
+
+// AddInts returns a + b, reporting false if the sum overflows
+func AddInts(a, b int64) (int64, bool) {
+	sum := a + b
+	return sum, (sum > a) == (b > 0)
+}
+
+// SubInts returns a - b, reporting false if the difference overflows
+func SubInts(a, b int64) (int64, bool) {
+	diff := a - b
+	return diff, (diff < a) == (b > 0)
+}
+
+// MulInts returns a * b, reporting false if the product overflows
+func MulInts(a, b int64) (int64, bool) {
+	hi, lo := bits.Mul64(magnitude(a), magnitude(b))
+	negative := (a < 0) != (b < 0)
+	return a * b, hi == 0 && (lo <= math.MaxInt64 || (negative && lo == 1<<63))
+}
+
+// PowInts returns base ** exp for a non-negative exponent by squaring,
+// reporting false if the power overflows
+func PowInts(base, exp int64) (int64, bool) {
+	result := int64(1)
+	for exp > 0 {
+		var ok bool
+		if exp&1 == 1 {
+			if result, ok = MulInts(result, base); !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			if base, ok = MulInts(base, base); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
+
+// magnitude returns |n| as an unsigned integer, which holds the magnitude
+// of MinInt64
+func magnitude(n int64) uint64 {
+	if n < 0 {
+		return uint64(-n)
+	}
+	return uint64(n)
+}
+
+// Add returns a + b for two ints or floats like PHP: integer sums that
+// overflow become floats instead of wrapping around
+func Add(a, b *Value) *Value {
+	if a.IsInt() && b.IsInt() {
+		if sum, ok := AddInts(a.ToInt(), b.ToInt()); ok {
+			return NewInt(sum)
+		}
+	}
+	return NewFloat(a.ToFloat() + b.ToFloat())
+}
+
+// Sub returns a - b for two ints or floats, promoting overflowing integer
+// differences to float
+func Sub(a, b *Value) *Value {
+	if a.IsInt() && b.IsInt() {
+		if diff, ok := SubInts(a.ToInt(), b.ToInt()); ok {
+			return NewInt(diff)
+		}
+	}
+	return NewFloat(a.ToFloat() - b.ToFloat())
+}
+
+// Mul returns a * b for two ints or floats, promoting overflowing integer
+// products to float
+func Mul(a, b *Value) *Value {
+	if a.IsInt() && b.IsInt() {
+		if product, ok := MulInts(a.ToInt(), b.ToInt()); ok {
+			return NewInt(product)
+		}
+	}
+	return NewFloat(a.ToFloat() * b.ToFloat())
+}
+
+// Pow returns a ** b for two ints or floats: integer powers with a
+// non-negative exponent stay integers unless they overflow
+func Pow(a, b *Value) *Value {
+	if a.IsInt() && b.IsInt() && b.ToInt() >= 0 {
+		if power, ok := PowInts(a.ToInt(), b.ToInt()); ok {
+			return NewInt(power)
+		}
+	}
+	return NewFloat(math.Pow(a.ToFloat(), b.ToFloat()))
+}
@@ -0,0 +1,147 @@
+package types
+
+import (
+	"math"
+	"testing"
+)
+
+// ============================================================================
+// Numeric String Tests
+// ============================================================================
+
+func TestParseNumeric(t *testing.T) {
+	tests := []struct {
+		input string
+		want  *Value
+		kind  NumericKind
+	}{
+		{"42", NewInt(42), Numeric},
+		{"  -17", NewInt(-17), Numeric},
+		{"42  ", NewInt(42), Numeric},
+		{"+3", NewInt(3), Numeric},
+		{"1.5", NewFloat(1.5), Numeric},
+		{".5", NewFloat(0.5), Numeric},
+		{"5.", NewFloat(5), Numeric},
+		{"1e3", NewFloat(1000), Numeric},
+		{"-2.5E-1", NewFloat(-0.25), Numeric},
+		{"9223372036854775808", NewFloat(9223372036854775808), Numeric},
+		{"123abc", NewInt(123), LeadingNumeric},
+		{"1.5 apples", NewFloat(1.5), LeadingNumeric},
+		{"1e", NewInt(1), LeadingNumeric},
+		{"abc", NewInt(0), NotNumeric},
+		{"", NewInt(0), NotNumeric},
+		{".", NewInt(0), NotNumeric},
+		{"0x1A", NewInt(0), LeadingNumeric},
+	}
+
+	for _, tt := range tests {
+		got, kind := ParseNumeric(tt.input)
+		if kind != tt.kind {
+			t.Errorf("ParseNumeric(%q) kind = %v, want %v", tt.input, kind, tt.kind)
+		}
+		if !got.Identical(tt.want) {
+			t.Errorf("ParseNumeric(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+// ============================================================================
+// Increment/Decrement Tests
+// ============================================================================
+
+func TestIncrement(t *testing.T) {
+	tests := []struct {
+		name  string
+		input *Value
+		want  *Value
+	}{
+		{"int", NewInt(5), NewInt(6)},
+		{"int overflow", NewInt(math.MaxInt64), NewFloat(math.MaxInt64 + 1.0)},
+		{"float", NewFloat(1.5), NewFloat(2.5)},
+		{"null", NewNull(), NewInt(1)},
+		{"bool", NewBool(false), NewBool(false)},
+		{"numeric string", NewString("5"), NewInt(6)},
+		{"float string", NewString("1.5"), NewFloat(2.5)},
+		{"empty string", NewString(""), NewString("1")},
+		{"letter", NewString("a"), NewString("b")},
+		{"carry", NewString("Az"), NewString("Ba")},
+		{"digit carry", NewString("a9"), NewString("b0")},
+		{"prepend lower", NewString("zz"), NewString("aaa")},
+		{"prepend upper", NewString("Zz"), NewString("AAa")},
+		{"prepend digit", NewString("9"), NewInt(10)},
+		{"leading numeric", NewString("1a9"), NewString("1b0")},
+		{"non-alphanumeric", NewString("a-"), NewString("a-")},
+	}
+
+	for _, tt := range tests {
+		got, ok := tt.input.Increment()
+		if !ok {
+			t.Errorf("%s: Increment() not supported", tt.name)
+			continue
+		}
+		if !got.Identical(tt.want) {
+			t.Errorf("%s: Increment() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, ok := NewArray(NewEmptyArray()).Increment(); ok {
+		t.Error("Expected arrays not to support Increment()")
+	}
+}
+
+func TestDecrement(t *testing.T) {
+	tests := []struct {
+		name  string
+		input *Value
+		want  *Value
+	}{
+		{"int", NewInt(5), NewInt(4)},
+		{"int overflow", NewInt(math.MinInt64), NewFloat(math.MinInt64 - 1.0)},
+		{"null", NewNull(), NewNull()},
+		{"numeric string", NewString("5"), NewInt(4)},
+		{"empty string", NewString(""), NewInt(-1)},
+		{"non-numeric string", NewString("abc"), NewString("abc")},
+		{"bool", NewBool(true), NewBool(true)},
+	}
+
+	for _, tt := range tests {
+		got, ok := tt.input.Decrement()
+		if !ok {
+			t.Errorf("%s: Decrement() not supported", tt.name)
+			continue
+		}
+		if !got.Identical(tt.want) {
+			t.Errorf("%s: Decrement() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// ============================================================================
+// Integer Overflow Tests
+// ============================================================================
+
+func TestIntegerOverflow(t *testing.T) {
+	tests := []struct {
+		name string
+		got  *Value
+		want *Value
+	}{
+		{"add", Add(NewInt(1), NewInt(2)), NewInt(3)},
+		{"add overflow", Add(NewInt(math.MaxInt64), NewInt(1)), NewFloat(math.MaxInt64 + 1.0)},
+		{"sub overflow", Sub(NewInt(math.MinInt64), NewInt(1)), NewFloat(math.MinInt64 - 1.0)},
+		{"mul", Mul(NewInt(-3), NewInt(4)), NewInt(-12)},
+		{"mul min int", Mul(NewInt(math.MinInt64), NewInt(1)), NewInt(math.MinInt64)},
+		{"mul overflow", Mul(NewInt(math.MinInt64), NewInt(-1)), NewFloat(-float64(math.MinInt64))},
+		{"mul float", Mul(NewInt(2), NewFloat(1.5)), NewFloat(3)},
+		{"pow", Pow(NewInt(2), NewInt(62)), NewInt(1 << 62)},
+		{"pow overflow", Pow(NewInt(2), NewInt(64)), NewFloat(math.Pow(2, 64))},
+		{"pow of one", Pow(NewInt(1), NewInt(math.MaxInt64)), NewInt(1)},
+		{"pow negative exponent", Pow(NewInt(2), NewInt(-1)), NewFloat(0.5)},
+	}
+
+	for _, tt := range tests {
+		if !tt.got.Identical(tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
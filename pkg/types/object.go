@@ -1,6 +1,11 @@
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
 
 // ============================================================================
 // Object Structure
@@ -14,8 +19,19 @@ type Object struct {
 	Properties map[string]*Property  // Instance properties (key: property name)
 	ObjectID   uint64                // Unique object identifier for identity comparison
 
+	// Property keys in declaration order, then the dynamic properties in
+	// the order they were added (see AddProperty)
+	PropertyOrder []string
+
+	// Engine-private payload for built-in classes (e.g. the callable wrapped by a Closure)
+	Internal interface{}
+
 	// Object state
 	IsDestroyed bool // Whether __destruct() has been called
+
+	// Garbage collection (see gc.go)
+	refcount atomic.Int32 // Counted holders of the object
+	roots    *RootBuffer  // Buffer of the possible cycle roots, if tracked
 }
 
 // Property represents an object property with metadata
@@ -28,6 +44,10 @@ type Property struct {
 	Default    *Value            // Default value
 	IsReadOnly bool              // Readonly property (PHP 8.1+)
 	Hooks      *PropertyHooks    // Property hooks (PHP 8.4+)
+
+	// Class that declared the property; empty for the object's own class
+	// and dynamic properties
+	DeclaringClass string
 }
 
 // PropertyVisibility defines property access levels
@@ -76,6 +96,8 @@ type ClassEntry struct {
 	ShortName  string // Short name (without namespace)
 	Namespace  string // Namespace the class belongs to
 	FileName   string // File where class was declared
+	Attributes []*Attribute // Attributes declared on the class (PHP 8.0+)
+	DocComment string       // /** */ comment preceding the declaration
 
 	// Class modifiers
 	IsFinal    bool // final class (cannot be extended)
@@ -94,6 +116,7 @@ type ClassEntry struct {
 
 	// Properties
 	Properties       map[string]*PropertyDef // All properties (static + instance)
+	PropertyOrder    []string                // Property keys in declaration order, inherited ones first
 	StaticProperties map[string]*Value       // Static property values (shared across instances)
 	DefaultProperties map[string]*Value      // Default values for instance properties
 
@@ -111,6 +134,9 @@ type ClassEntry struct {
 	// Enum specific data
 	EnumBackingType string           // Backing type for backed enums ("int" or "string")
 	EnumCases       map[string]*Value // Enum cases (name => value)
+	EnumCaseOrder   []string          // Enum case names in declaration order
+
+	enumCaseObjects map[string]*Value // Case singletons, created on first use
 }
 
 // PropertyDef defines a class property with metadata
@@ -124,6 +150,8 @@ type PropertyDef struct {
 	IsReadOnly   bool               // readonly property (PHP 8.1+)
 	Hooks        *PropertyHooks     // Property hooks (PHP 8.4+)
 	DeclaringClass string           // Which class declared this property (for private props)
+	Attributes   []*Attribute       // Attributes declared on the property (PHP 8.0+)
+	DocComment   string             // /** */ comment preceding the declaration
 }
 
 // MethodDef defines a class method with metadata
@@ -134,7 +162,9 @@ type MethodDef struct {
 	IsFinal        bool               // final method (cannot be overridden)
 	IsAbstract     bool               // abstract method (no implementation)
 	Instructions   []interface{}      // Bytecode instructions
+	Constants      []interface{}      // Constant table of the instructions
 	NumLocals      int                // Number of local variables
+	Variables      []string           // Compiled variable names by index
 	NumParams      int                // Number of parameters
 	Parameters     []*ParameterDef    // Parameter definitions
 	ReturnType     string             // Return type declaration
@@ -143,6 +173,23 @@ type MethodDef struct {
 	IsDestructor   bool               // Is this __destruct?
 	IsMagic        bool               // Is this a magic method?
 	DeclaringClass string             // Which class declared this method
+	TryCatch       []TryCatchElement  // Exception regions of the method body
+	Handler        interface{}        // Engine-implemented body for built-in classes (nil for user code)
+	Attributes     []*Attribute       // Attributes declared on the method (PHP 8.0+)
+	DocComment     string             // /** */ comment preceding the declaration
+	StrictTypes    bool               // Compiled in a declare(strict_types=1) file
+	File           string             // Path of the file declaring it ("" if unknown)
+}
+
+// TryCatchElement describes one try/catch/finally region of a function body.
+// All fields are instruction offsets; CatchOp and FinallyOp are 0 when the
+// region has no catch or finally block. FinallyEnd points at the FAST_RET
+// instruction closing the finally block.
+type TryCatchElement struct {
+	TryOp      int // First instruction of the try block
+	CatchOp    int // First CATCH instruction
+	FinallyOp  int // First instruction of the finally block
+	FinallyEnd int // FAST_RET instruction ending the finally block
 }
 
 // ParameterDef defines a method parameter
@@ -155,6 +202,7 @@ type ParameterDef struct {
 	PassedByRef  bool    // Passed by reference
 	IsPromoted   bool    // Constructor promoted property (PHP 8.0+)
 	Visibility   PropertyVisibility // Visibility if promoted
+	Attributes   []*Attribute       // Attributes declared on the parameter (PHP 8.0+)
 }
 
 // ClassConstant represents a class constant with visibility
@@ -163,6 +211,37 @@ type ClassConstant struct {
 	Value      *Value             // Constant value
 	Visibility PropertyVisibility // public, protected, private (PHP 7.1+)
 	IsFinal    bool               // final constant (PHP 8.1+) - cannot be overridden
+	Attributes []*Attribute       // Attributes declared on the constant (PHP 8.0+)
+	DocComment string             // /** */ comment preceding the declaration
+}
+
+// Attribute is an attribute (#[Name(args)]) declared on a class, function,
+// method, property, class constant or parameter. Its class is only
+// instantiated when reflection asks for an instance.
+type Attribute struct {
+	Name      string              // Fully qualified attribute class name
+	Arguments []AttributeArgument // Arguments in declaration order
+}
+
+// AttributeArgument is an argument of an attribute; Name is empty for
+// positional arguments. Arguments referring to class constants keep their
+// expression in Expr, evaluated when the arguments are read.
+type AttributeArgument struct {
+	Name  string
+	Value *Value
+	Expr  *ConstantExpr
+}
+
+// ConstantExpr is a constant expression whose value is only known at run
+// time: a value, a Class::CONSTANT reference, or a binary operation on two
+// constant expressions
+type ConstantExpr struct {
+	Value    *Value
+	Class    string // Class of a constant reference
+	Constant string // Constant name of a constant reference
+	Operator string // Operator of a binary operation
+	Left     *ConstantExpr
+	Right    *ConstantExpr
 }
 
 // InterfaceEntry represents a PHP interface
@@ -178,6 +257,7 @@ type TraitEntry struct {
 	Name       string                 // Trait name
 	Properties map[string]*PropertyDef // Trait properties
 	Methods    map[string]*MethodDef   // Trait methods
+	Constants  map[string]*ClassConstant // Trait constants (PHP 8.2+)
 	UsedTraits []*TraitEntry           // Traits used by this trait
 }
 
@@ -194,6 +274,7 @@ func NewObjectFromClass(classEntry *ClassEntry) *Object {
 		ObjectID:   nextObjectID(),
 		IsDestroyed: false,
 	}
+	obj.PropertyOrder = classEntry.PropertyKeys()
 
 	// Initialize instance properties with default values
 	for name, propDef := range classEntry.Properties {
@@ -204,15 +285,17 @@ func NewObjectFromClass(classEntry *ClassEntry) *Object {
 				value = propDef.Default.Copy()
 			}
 
+			// All properties of a readonly class are readonly
 			prop := &Property{
-				Value:      value,
-				Visibility: propDef.Visibility,
-				IsStatic:   false,
-				Type:       propDef.Type,
-				HasDefault: propDef.HasDefault,
-				Default:    propDef.Default, // Keep original for reference
-				IsReadOnly: propDef.IsReadOnly,
-				Hooks:      propDef.Hooks,
+				Value:          value,
+				Visibility:     propDef.Visibility,
+				IsStatic:       false,
+				Type:           propDef.Type,
+				HasDefault:     propDef.HasDefault,
+				Default:        propDef.Default, // Keep original for reference
+				IsReadOnly:     propDef.IsReadOnly || classEntry.IsReadOnly,
+				Hooks:          propDef.Hooks,
+				DeclaringClass: propDef.DeclaringClass,
 			}
 			obj.Properties[name] = prop
 		}
@@ -232,12 +315,12 @@ func NewObjectInstance(className string) *Object {
 }
 
 // Global object ID counter for unique object identification
-var objectIDCounter uint64 = 0
+// (atomic, since parallel tasks create objects concurrently)
+var objectIDCounter atomic.Uint64
 
 // nextObjectID generates a unique object ID
 func nextObjectID() uint64 {
-	objectIDCounter++
-	return objectIDCounter
+	return objectIDCounter.Add(1)
 }
 
 // NextObjectID generates a unique object ID (exported for use by VM)
@@ -249,9 +332,44 @@ func NextObjectID() uint64 {
 // Property Access Methods
 // ============================================================================
 
+// PrivatePropertyKey returns the key a private property of class is kept
+// under in the objects of its subclasses that declare a property of the
+// same name, as PHP mangles it: "\0Class\0name"
+func PrivatePropertyKey(class, name string) string {
+	return "\x00" + class + "\x00" + name
+}
+
+// SplitPropertyKey returns the declaring class and the name of a property
+// key; the class is empty for the keys of unshadowed properties
+func SplitPropertyKey(key string) (class, name string) {
+	if strings.HasPrefix(key, "\x00") {
+		if class, name, ok := strings.Cut(key[1:], "\x00"); ok {
+			return class, name
+		}
+	}
+	return "", key
+}
+
+// PropertyKey returns the key a property name refers to from the given
+// class scope: the private property of the scope's class when a subclass
+// shadows it, else the property of that name
+func (o *Object) PropertyKey(name string, scope *ClassEntry) string {
+	if scope == nil || scope == o.ClassEntry || o.ClassEntry == nil {
+		return name
+	}
+	key := PrivatePropertyKey(scope.Name, name)
+	if _, declared := o.ClassEntry.Properties[key]; declared {
+		return key
+	}
+	if _, exists := o.Properties[key]; exists {
+		return key
+	}
+	return name
+}
+
 // GetProperty gets a property value with visibility checking
 func (o *Object) GetProperty(name string, accessContext *ClassEntry) (*Value, bool) {
-	prop, exists := o.Properties[name]
+	prop, exists := o.Properties[o.PropertyKey(name, accessContext)]
 	if !exists {
 		return nil, false
 	}
@@ -272,27 +390,51 @@ func (o *Object) GetProperty(name string, accessContext *ClassEntry) (*Value, bo
 
 // SetProperty sets a property value with visibility and readonly checking
 func (o *Object) SetProperty(name string, value *Value, accessContext *ClassEntry) bool {
-	prop, exists := o.Properties[name]
+	return o.AssignProperty(name, value, accessContext) == nil
+}
+
+// PropertyError is returned when a property cannot be written; its message
+// is the one of the Error PHP throws
+type PropertyError struct {
+	Message string
+}
+
+func (e *PropertyError) Error() string {
+	return e.Message
+}
+
+// AssignProperty sets a property value from the given class scope (nil
+// for the global scope). A readonly property can be initialized exactly
+// once, and only from the scope of the class declaring it; the objects
+// of readonly classes cannot get dynamic properties.
+func (o *Object) AssignProperty(name string, value *Value, scope *ClassEntry) error {
+	key := o.PropertyKey(name, scope)
+	prop, exists := o.Properties[key]
 	if !exists {
+		if o.ClassEntry != nil && o.ClassEntry.IsReadOnly {
+			return &PropertyError{fmt.Sprintf("Cannot create dynamic property %s::$%s", o.ClassName, name)}
+		}
 		// Dynamic property creation (if allowed)
 		prop = &Property{
 			Value:      value,
 			Visibility: VisibilityPublic,
 			IsStatic:   false,
 		}
-		o.Properties[name] = prop
-		return true
+		o.AddProperty(key, prop)
+		AddRef(value)
+		return nil
 	}
 
-	// Check visibility
-	if !canAccessProperty(prop, accessContext, o.ClassEntry) {
-		return false
+	// Check readonly
+	if prop.IsReadOnly {
+		if err := o.checkReadonly(name, prop, scope, "modify", "initialize"); err != nil {
+			return err
+		}
 	}
 
-	// Check readonly
-	if prop.IsReadOnly && prop.Value != nil {
-		// Readonly properties can only be set once during construction
-		return false
+	// Check visibility
+	if !canAccessProperty(prop, scope, o.ClassEntry) {
+		return &PropertyError{fmt.Sprintf("Cannot access %s property %s::$%s", prop.Visibility, o.ClassName, name)}
 	}
 
 	// Execute set hook if present
@@ -301,8 +443,138 @@ func (o *Object) SetProperty(name string, value *Value, accessContext *ClassEntr
 		// For now, set the value directly
 	}
 
+	AddRef(value)
+	DelRef(prop.Value)
 	prop.Value = value
-	return true
+	return nil
+}
+
+// UnsetProperty removes a property from the given class scope. Readonly
+// properties cannot be unset once initialized; an uninitialized one stays
+// declared, so it can still be initialized.
+func (o *Object) UnsetProperty(name string, scope *ClassEntry) error {
+	key := o.PropertyKey(name, scope)
+	prop, exists := o.Properties[key]
+	if !exists {
+		return nil
+	}
+	if prop.IsReadOnly {
+		return o.checkReadonly(name, prop, scope, "unset", "unset")
+	}
+	delete(o.Properties, key)
+	DelRef(prop.Value)
+	// A dynamic property set again is added last
+	if o.ClassEntry == nil || o.ClassEntry.Properties[key] == nil {
+		o.PropertyOrder = removeKey(o.PropertyOrder, key)
+	}
+	return nil
+}
+
+// AddProperty sets a property of the object, placing a new one after the
+// properties the object already has
+func (o *Object) AddProperty(key string, prop *Property) {
+	if _, exists := o.Properties[key]; !exists {
+		o.PropertyOrder = append(o.PropertyOrder, key)
+	}
+	o.Properties[key] = prop
+}
+
+// PropertyKeys returns the keys of the object's properties in order: the
+// declared ones in declaration order, then the dynamic ones in the order
+// they were added. Properties set without AddProperty come last, by name.
+func (o *Object) PropertyKeys() []string {
+	keys := make([]string, 0, len(o.Properties))
+	for key := range o.Properties {
+		keys = append(keys, key)
+	}
+	return orderKeys(keys, o.PropertyOrder)
+}
+
+// PropertyKeys returns the keys of the class's properties in declaration
+// order, the inherited ones first. Properties declared without recording
+// their position (by built-in classes) come last, by name.
+func (ce *ClassEntry) PropertyKeys() []string {
+	if len(ce.PropertyOrder) == len(ce.Properties) {
+		// Full slice expression: appending copies rather than sharing
+		return ce.PropertyOrder[:len(ce.PropertyOrder):len(ce.PropertyOrder)]
+	}
+	keys := make([]string, 0, len(ce.Properties))
+	for key := range ce.Properties {
+		keys = append(keys, key)
+	}
+	return orderKeys(keys, ce.PropertyOrder)
+}
+
+// orderKeys sorts keys by their first position in order, the keys order
+// does not list last, by name
+func orderKeys(keys, order []string) []string {
+	position := make(map[string]int, len(order))
+	for i, key := range order {
+		if _, seen := position[key]; !seen {
+			position[key] = i
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, aOrdered := position[keys[i]]
+		b, bOrdered := position[keys[j]]
+		if aOrdered != bOrdered {
+			return aOrdered
+		}
+		if aOrdered {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// containsKey reports whether keys lists key
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// removeKey returns keys without key
+func removeKey(keys []string, key string) []string {
+	for i, k := range keys {
+		if k == key {
+			return append(keys[:i:i], keys[i+1:]...)
+		}
+	}
+	return keys
+}
+
+// checkReadonly checks that a readonly property may be initialized (or,
+// for unset, left uninitialized) from scope. action and scopedAction name
+// the operation in the errors for initialized properties and for writes
+// from outside the declaring class.
+func (o *Object) checkReadonly(name string, prop *Property, scope *ClassEntry, action, scopedAction string) error {
+	declaring := prop.DeclaringClass
+	if declaring == "" {
+		declaring = o.ClassName
+	}
+
+	if prop.Value != nil {
+		return &PropertyError{fmt.Sprintf("Cannot %s readonly property %s::$%s", action, declaring, name)}
+	}
+	if scope == nil {
+		return &PropertyError{fmt.Sprintf("Cannot %s readonly property %s::$%s from global scope", scopedAction, declaring, name)}
+	}
+	if !strings.EqualFold(scope.Name, declaring) {
+		return &PropertyError{fmt.Sprintf("Cannot %s readonly property %s::$%s from scope %s", scopedAction, declaring, name, scope.Name)}
+	}
+	return nil
+}
+
+// IsReadonlyInitialized reports whether a property is readonly and already
+// initialized, so it can no longer be modified in any way
+func (o *Object) IsReadonlyInitialized(name string) bool {
+	prop, exists := o.Properties[name]
+	return exists && prop.IsReadOnly && prop.Value != nil
 }
 
 // canAccessProperty checks if a property can be accessed from a given context
@@ -315,7 +587,13 @@ func canAccessProperty(prop *Property, accessContext *ClassEntry, ownerClass *Cl
 		return accessContext != nil && (accessContext == ownerClass || isSubclassOf(accessContext, ownerClass))
 	case VisibilityPrivate:
 		// Only accessible from the declaring class
-		return accessContext != nil && accessContext == ownerClass
+		if accessContext == nil {
+			return false
+		}
+		if prop.DeclaringClass != "" {
+			return strings.EqualFold(accessContext.Name, prop.DeclaringClass)
+		}
+		return accessContext == ownerClass
 	default:
 		return false
 	}
@@ -337,6 +615,69 @@ func isSubclassOf(childClass, parentClass *ClassEntry) bool {
 	return false
 }
 
+// ============================================================================
+// Debug View
+// ============================================================================
+
+// DebugProperty is a single entry of an object's debug view, as shown by
+// var_dump, print_r and the debugger's variable inspection
+type DebugProperty struct {
+	Key        *Value             // Property name (or __debugInfo array key)
+	Value      *Value             // Property value
+	Visibility PropertyVisibility // Visibility annotation
+	Class      string             // Declaring class (shown for private properties)
+}
+
+// DebugProperties returns the raw instance properties of the object, in
+// the order of PropertyKeys
+func (o *Object) DebugProperties() []DebugProperty {
+	keys := o.PropertyKeys()
+	props := make([]DebugProperty, 0, len(keys))
+	for _, key := range keys {
+		prop := o.Properties[key]
+		if prop.IsStatic {
+			continue
+		}
+		value := prop.Value
+		if value == nil {
+			value = NewNull()
+		}
+
+		class, name := SplitPropertyKey(key)
+		if class == "" {
+			class = o.ClassName
+			if o.ClassEntry != nil {
+				if def, ok := o.ClassEntry.Properties[name]; ok && def.DeclaringClass != "" {
+					class = def.DeclaringClass
+				}
+			}
+		}
+
+		props = append(props, DebugProperty{
+			Key:        NewString(name),
+			Value:      value,
+			Visibility: prop.Visibility,
+			Class:      class,
+		})
+	}
+	return props
+}
+
+// DebugPropertiesFromArray converts a __debugInfo() result into debug view entries.
+// All entries are rendered as public, with their array keys preserved.
+func DebugPropertiesFromArray(arr *Array) []DebugProperty {
+	props := make([]DebugProperty, 0, arr.Len())
+	arr.Each(func(key, value *Value) bool {
+		props = append(props, DebugProperty{
+			Key:        key,
+			Value:      value,
+			Visibility: VisibilityPublic,
+		})
+		return true
+	})
+	return props
+}
+
 // ============================================================================
 // Method Access
 // ============================================================================
@@ -356,10 +697,12 @@ func (ce *ClassEntry) GetMethod(name string) (*MethodDef, bool) {
 	return nil, false
 }
 
-// ImplementsInterface checks if the class implements an interface
+// ImplementsInterface checks if the class implements an interface, directly,
+// through a parent class or through interface inheritance (names are
+// case-insensitive)
 func (ce *ClassEntry) ImplementsInterface(interfaceName string) bool {
 	for _, iface := range ce.Interfaces {
-		if iface.Name == interfaceName {
+		if strings.EqualFold(iface.Name, interfaceName) {
 			return true
 		}
 		// Check parent interfaces
@@ -379,7 +722,7 @@ func (ce *ClassEntry) ImplementsInterface(interfaceName string) bool {
 // ifaceImplements checks if an interface extends another interface
 func ifaceImplements(iface *InterfaceEntry, interfaceName string) bool {
 	for _, parent := range iface.ParentInterfaces {
-		if parent.Name == interfaceName {
+		if strings.EqualFold(parent.Name, interfaceName) {
 			return true
 		}
 		if ifaceImplements(parent, interfaceName) {
@@ -467,21 +810,44 @@ func (ce *ClassEntry) InheritFrom(parent *ClassEntry) error {
 		return fmt.Errorf("Class %s cannot extend final class %s", ce.Name, parent.Name)
 	}
 
+	// A readonly class can only extend, and be extended by, readonly classes
+	if parent.IsReadOnly && !ce.IsReadOnly {
+		return fmt.Errorf("Non-readonly class %s cannot extend readonly class %s", ce.Name, parent.Name)
+	}
+	if ce.IsReadOnly && !parent.IsReadOnly {
+		return fmt.Errorf("Readonly class %s cannot extend non-readonly class %s", ce.Name, parent.Name)
+	}
+
 	// Set parent reference
 	ce.ParentClass = parent
 
-	// Inherit properties (skip private properties)
-	for name, parentProp := range parent.Properties {
-		// Skip private properties
+	// Inherit properties. The private instance properties of the parent
+	// stay its own: one the child redeclares is kept under a key of the
+	// parent class. The inherited properties keep their positions, before
+	// the ones the child adds.
+	own := ce.PropertyKeys()
+	order := make([]string, 0, len(parent.Properties)+len(own))
+	for _, name := range parent.PropertyKeys() {
+		parentProp := parent.Properties[name]
 		if parentProp.Visibility == VisibilityPrivate {
-			continue
+			if parentProp.IsStatic {
+				continue
+			}
+			if _, exists := ce.Properties[name]; exists {
+				declaring := parentProp.DeclaringClass
+				if declaring == "" {
+					declaring = parent.Name
+				}
+				name = PrivatePropertyKey(declaring, name)
+			}
 		}
 
 		// If child doesn't override this property, inherit it
 		if _, exists := ce.Properties[name]; !exists {
 			// Create a copy of the property definition
+			_, propName := SplitPropertyKey(name)
 			inheritedProp := &PropertyDef{
-				Name:           name,
+				Name:           propName,
 				Visibility:     parentProp.Visibility,
 				IsStatic:       parentProp.IsStatic,
 				Type:           parentProp.Type,
@@ -490,13 +856,23 @@ func (ce *ClassEntry) InheritFrom(parent *ClassEntry) error {
 				IsReadOnly:     parentProp.IsReadOnly,
 				Hooks:          parentProp.Hooks,
 				DeclaringClass: parentProp.DeclaringClass,
+				Attributes:     parentProp.Attributes,
+				DocComment:     parentProp.DocComment,
 			}
 			if inheritedProp.DeclaringClass == "" {
 				inheritedProp.DeclaringClass = parent.Name
 			}
 			ce.Properties[name] = inheritedProp
 		}
+		order = append(order, name)
 	}
+	inherited := len(order)
+	for _, name := range own {
+		if !containsKey(order[:inherited], name) {
+			order = append(order, name)
+		}
+	}
+	ce.PropertyOrder = order
 
 	// Inherit methods (skip private methods and constructors)
 	for name, parentMethod := range parent.Methods {
@@ -525,15 +901,20 @@ func (ce *ClassEntry) InheritFrom(parent *ClassEntry) error {
 				IsFinal:        parentMethod.IsFinal,
 				IsAbstract:     parentMethod.IsAbstract,
 				Instructions:   parentMethod.Instructions,
+				Constants:      parentMethod.Constants,
 				NumLocals:      parentMethod.NumLocals,
+				Variables:      parentMethod.Variables,
 				NumParams:      parentMethod.NumParams,
 				Parameters:     parentMethod.Parameters,
 				ReturnType:     parentMethod.ReturnType,
 				ReturnByRef:    parentMethod.ReturnByRef,
 				IsMagic:        parentMethod.IsMagic,
 				DeclaringClass: parentMethod.DeclaringClass,
+				TryCatch:       parentMethod.TryCatch,
+				Handler:        parentMethod.Handler,
 			}
-			if inheritedMethod.DeclaringClass == "" {
+			// The methods of the parent's traits run in the parent's scope
+			if inheritedMethod.DeclaringClass == "" || parent.usesTrait(inheritedMethod.DeclaringClass) {
 				inheritedMethod.DeclaringClass = parent.Name
 			}
 			ce.Methods[name] = inheritedMethod
@@ -569,13 +950,17 @@ func (ce *ClassEntry) InheritFrom(parent *ClassEntry) error {
 				IsFinal:        parentMagic.IsFinal,
 				IsAbstract:     parentMagic.IsAbstract,
 				Instructions:   parentMagic.Instructions,
+				Constants:      parentMagic.Constants,
 				NumLocals:      parentMagic.NumLocals,
+				Variables:      parentMagic.Variables,
 				NumParams:      parentMagic.NumParams,
 				Parameters:     parentMagic.Parameters,
 				ReturnType:     parentMagic.ReturnType,
 				ReturnByRef:    parentMagic.ReturnByRef,
 				IsMagic:        true,
 				DeclaringClass: parentMagic.DeclaringClass,
+				TryCatch:       parentMagic.TryCatch,
+				Handler:        parentMagic.Handler,
 			}
 			if inheritedMagic.DeclaringClass == "" {
 				inheritedMagic.DeclaringClass = parent.Name
@@ -587,6 +972,16 @@ func (ce *ClassEntry) InheritFrom(parent *ClassEntry) error {
 	return nil
 }
 
+// usesTrait reports whether the class uses the named trait itself
+func (ce *ClassEntry) usesTrait(name string) bool {
+	for _, trait := range ce.Traits {
+		if strings.EqualFold(trait.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
 // validateMethodOverride checks if a method override is valid
 func validateMethodOverride(parentMethod, childMethod *MethodDef, parentClassName, childClassName string) error {
 	// Cannot override final methods
@@ -633,19 +1028,77 @@ func isVisibilityCompatible(parent, child PropertyVisibility) bool {
 
 // HasAbstractMethods returns true if the class has any unimplemented abstract methods
 func (ce *ClassEntry) HasAbstractMethods() bool {
-	// Check own methods
-	for _, method := range ce.Methods {
-		if method.IsAbstract {
-			return true
+	return len(ce.UnimplementedAbstractMethods()) > 0
+}
+
+// UnimplementedAbstractMethods returns the abstract methods of the class
+// hierarchy and of its interfaces that no class in the hierarchy
+// implements, as sorted "DeclaringClass::method" names
+func (ce *ClassEntry) UnimplementedAbstractMethods() []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	// A method is implemented when the nearest declaration is not abstract
+	for class := ce; class != nil; class = class.ParentClass {
+		for name, method := range class.Methods {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if method.IsAbstract {
+				declaringClass := method.DeclaringClass
+				if declaringClass == "" {
+					declaringClass = class.Name
+				}
+				names = append(names, declaringClass+"::"+name)
+			}
 		}
 	}
 
-	// Check parent
-	if ce.ParentClass != nil {
-		return ce.ParentClass.HasAbstractMethods()
+	var visit func(iface *InterfaceEntry)
+	visit = func(iface *InterfaceEntry) {
+		for name := range iface.Methods {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, iface.Name+"::"+name)
+			}
+		}
+		for _, parent := range iface.ParentInterfaces {
+			visit(parent)
+		}
+	}
+	for class := ce; class != nil; class = class.ParentClass {
+		for _, iface := range class.Interfaces {
+			visit(iface)
+		}
 	}
 
-	return false
+	sort.Strings(names)
+	return names
+}
+
+// ValidateAbstractMethods checks that a concrete class implements every
+// abstract method it inherits or declares
+func (ce *ClassEntry) ValidateAbstractMethods() error {
+	if !ce.IsInstantiable() {
+		return nil
+	}
+	missing := ce.UnimplementedAbstractMethods()
+	if len(missing) == 0 {
+		return nil
+	}
+
+	plural := "s"
+	if len(missing) == 1 {
+		plural = ""
+	}
+	// Like PHP, at most three of the methods are listed
+	listed := strings.Join(missing, ", ")
+	if len(missing) > 3 {
+		listed = strings.Join(missing[:3], ", ") + ", ..."
+	}
+	return fmt.Errorf("Class %s contains %d abstract method%s and must therefore be declared abstract or implement the remaining methods (%s)",
+		ce.Name, len(missing), plural, listed)
 }
 
 // ============================================================================
@@ -685,6 +1138,7 @@ func NewTraitEntry(name string) *TraitEntry {
 		Name:       name,
 		Properties: make(map[string]*PropertyDef),
 		Methods:    make(map[string]*MethodDef),
+		Constants:  make(map[string]*ClassConstant),
 		UsedTraits: make([]*TraitEntry, 0),
 	}
 }
@@ -736,13 +1190,23 @@ func (ce *ClassEntry) ApplyTraits() error {
 	// Apply trait methods to class
 	for methodName, sources := range traitMethods {
 		// Skip if class already defines this method (class methods take precedence)
-		if existingMethod, exists := ce.Methods[methodName]; exists {
+		existingMethod, exists := ce.Methods[methodName]
+		if exists {
 			// Check if this is from the class itself, not inherited
 			if existingMethod.DeclaringClass == ce.Name || existingMethod.DeclaringClass == "" {
 				continue // Class method takes precedence
 			}
 		}
 
+		// Abstract trait methods are requirements: an implementation from
+		// another trait or the parent class satisfies them
+		sources = concreteTraitMethods(sources)
+		if sources[0].Method.IsAbstract {
+			if _, inherited := ce.GetMethod(methodName); inherited {
+				continue
+			}
+		}
+
 		// Check for conflicts
 		if len(sources) > 1 {
 			// Multiple traits define this method - need precedence resolution
@@ -807,8 +1271,19 @@ func (ce *ClassEntry) ApplyTraits() error {
 			}
 		}
 
-		// Copy the property to the class
-		ce.Properties[propName] = copyPropertyDef(sources[0].Property)
+		// Copy the property to the class, which declares it from then on
+		prop := copyPropertyDef(sources[0].Property)
+		prop.DeclaringClass = ce.Name
+		ce.Properties[propName] = prop
+	}
+
+	// Apply trait constants the class does not declare itself
+	for _, trait := range ce.Traits {
+		for name, constant := range trait.Constants {
+			if _, exists := ce.Constants[name]; !exists {
+				ce.Constants[name] = constant
+			}
+		}
 	}
 
 	// Apply trait aliases
@@ -829,18 +1304,36 @@ func (ce *ClassEntry) ApplyTraits() error {
 			visibility = methodParts[1]
 		}
 
-		// Find the trait
+		// Find the trait; without a trait name, the only trait defining the method
 		var sourceTrait *TraitEntry
 		for _, trait := range ce.Traits {
-			if trait.Name == traitName {
+			if traitName == "" {
+				if _, defines := trait.Methods[methodName]; !defines {
+					continue
+				}
+				if sourceTrait != nil {
+					return fmt.Errorf("An alias was defined for method %s(), which exists in both %s and %s. Use %s::%s or %s::%s to resolve the ambiguity",
+						methodName, sourceTrait.Name, trait.Name, sourceTrait.Name, methodName, trait.Name, methodName)
+				}
+				sourceTrait = trait
+			} else if trait.Name == traitName {
 				sourceTrait = trait
 				break
 			}
 		}
 
 		if sourceTrait == nil {
+			if traitName == "" {
+				return fmt.Errorf("An alias was defined for %s but this method does not exist", methodName)
+			}
 			return fmt.Errorf("Alias error: trait %s not used by class", traitName)
 		}
+		traitName = sourceTrait.Name
+
+		// A visibility change of a method the class declares itself does not apply
+		if own, exists := ce.Methods[aliasName]; exists && aliasName == methodName && own.DeclaringClass == ce.Name {
+			continue
+		}
 
 		// Find the method in the trait
 		sourceMethod, exists := sourceTrait.Methods[methodName]
@@ -954,9 +1447,33 @@ func (te *TraitEntry) ApplyUsedTraits() error {
 		te.Properties[propName] = copyPropertyDef(sources[0].Property)
 	}
 
+	// Apply constants to this trait
+	for _, usedTrait := range te.UsedTraits {
+		for name, constant := range usedTrait.Constants {
+			if _, exists := te.Constants[name]; !exists {
+				te.Constants[name] = constant
+			}
+		}
+	}
+
 	return nil
 }
 
+// concreteTraitMethods drops abstract declarations of a method when a trait
+// implements it
+func concreteTraitMethods(sources []*traitMethodSource) []*traitMethodSource {
+	var concrete []*traitMethodSource
+	for _, src := range sources {
+		if !src.Method.IsAbstract {
+			concrete = append(concrete, src)
+		}
+	}
+	if len(concrete) == 0 {
+		return sources[:1]
+	}
+	return concrete
+}
+
 // traitMethodSource tracks which trait a method came from
 type traitMethodSource struct {
 	TraitName string
@@ -978,7 +1495,9 @@ func copyMethodDef(method *MethodDef) *MethodDef {
 		IsFinal:        method.IsFinal,
 		IsAbstract:     method.IsAbstract,
 		Instructions:   method.Instructions,
+		Constants:      method.Constants,
 		NumLocals:      method.NumLocals,
+		Variables:      method.Variables,
 		NumParams:      method.NumParams,
 		Parameters:     method.Parameters,
 		ReturnType:     method.ReturnType,
@@ -987,6 +1506,11 @@ func copyMethodDef(method *MethodDef) *MethodDef {
 		IsDestructor:   method.IsDestructor,
 		IsMagic:        method.IsMagic,
 		DeclaringClass: method.DeclaringClass,
+		TryCatch:       method.TryCatch,
+		Handler:        method.Handler,
+		Attributes:     method.Attributes,
+		DocComment:     method.DocComment,
+		File:           method.File,
 	}
 }
 
@@ -1002,6 +1526,8 @@ func copyPropertyDef(prop *PropertyDef) *PropertyDef {
 		IsReadOnly:     prop.IsReadOnly,
 		Hooks:          prop.Hooks,
 		DeclaringClass: prop.DeclaringClass,
+		Attributes:     prop.Attributes,
+		DocComment:     prop.DocComment,
 	}
 }
 
@@ -1098,6 +1624,9 @@ func (ce *ClassEntry) AddCase(name string, value *Value) {
 	if !ce.IsEnum {
 		return
 	}
+	if _, exists := ce.EnumCases[name]; !exists {
+		ce.EnumCaseOrder = append(ce.EnumCaseOrder, name)
+	}
 	ce.EnumCases[name] = value
 }
 
@@ -1166,12 +1695,55 @@ func (ce *ClassEntry) GetCases() []string {
 	}
 
 	cases := make([]string, 0, len(ce.EnumCases))
-	for caseName := range ce.EnumCases {
-		cases = append(cases, caseName)
+	for _, caseName := range ce.EnumCaseOrder {
+		if _, exists := ce.EnumCases[caseName]; exists {
+			cases = append(cases, caseName)
+		}
 	}
 	return cases
 }
 
+// EnumCase returns the object of an enum case. Each case is a singleton,
+// so cases compare identical with ===; its readonly name property, and
+// value property for backed enums, expose the case.
+func (ce *ClassEntry) EnumCase(name string) (*Value, bool) {
+	value, exists := ce.EnumCases[name]
+	if !ce.IsEnum || !exists {
+		return nil, false
+	}
+	if object, ok := ce.enumCaseObjects[name]; ok {
+		return object, true
+	}
+
+	obj := NewObjectFromClass(ce)
+	obj.AddProperty("name", &Property{
+		Value: NewString(name), Visibility: VisibilityPublic, Type: "string", IsReadOnly: true,
+	})
+	if ce.EnumBackingType != "" {
+		obj.AddProperty("value", &Property{
+			Value: value, Visibility: VisibilityPublic, Type: ce.EnumBackingType, IsReadOnly: true,
+		})
+	}
+
+	if ce.enumCaseObjects == nil {
+		ce.enumCaseObjects = make(map[string]*Value)
+	}
+	object := NewObject(obj)
+	ce.enumCaseObjects[name] = object
+	return object, true
+}
+
+// EnumCaseFor returns the case of a backed enum whose backing value is
+// value, which must already have the backing type
+func (ce *ClassEntry) EnumCaseFor(value *Value) (*Value, bool) {
+	for _, caseName := range ce.GetCases() {
+		if backing := ce.EnumCases[caseName]; backing != nil && backing.Identical(value) {
+			return ce.EnumCase(caseName)
+		}
+	}
+	return nil, false
+}
+
 // From returns the case name for a given backing value (backed enums only)
 // Returns error if the value doesn't exist
 func (ce *ClassEntry) From(value *Value) (string, error) {
@@ -1223,18 +1795,62 @@ func (ce *ClassEntry) TryFrom(value *Value) (string, error) {
 // TypeInfo represents parsed type information
 type TypeInfo struct {
 	BaseType    string   // The base type (int, string, ClassName, etc.)
-	IsNullable  bool     // true if type starts with ?
+	IsNullable  bool     // true if null is accepted (?T, T|null, null, mixed)
 	IsUnion     bool     // true if type contains |
 	UnionTypes  []string // List of types in union (for int|string)
+	IsIntersection bool  // true for A&B
 	IsBuiltin   bool     // true for built-in types (int, string, etc.)
 	IsClass     bool     // true for class types
 	IsSelf      bool     // true for 'self' type
 	IsParent    bool     // true for 'parent' type
 	IsStatic    bool     // true for 'static' type
-}
 
-// ParseType parses a type string and returns type information
+	// The type in disjunctive normal form: a union of alternatives, each
+	// an intersection of one or more types. ?T is T|null and iterable is
+	// Traversable|array; builtin type names are lowercase.
+	Alternatives [][]string
+}
+
+// builtinTypeNames are the type names that do not refer to classes
+var builtinTypeNames = map[string]bool{
+	"int":      true,
+	"string":   true,
+	"float":    true,
+	"bool":     true,
+	"array":    true,
+	"object":   true,
+	"callable": true,
+	"iterable": true,
+	"mixed":    true,
+	"void":     true,
+	"never":    true,
+	"null":     true,
+	"false":    true,
+	"true":     true,
+}
+
+// IsBuiltinType reports whether a type name is a builtin type rather than
+// a class name (self, parent and static name classes)
+func IsBuiltinType(name string) bool {
+	return builtinTypeNames[strings.ToLower(name)]
+}
+
+// normalizeTypeName lowercases builtin and relative class type names and
+// strips the leading backslash of fully qualified class names
+func normalizeTypeName(name string) string {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "\\")
+	lower := strings.ToLower(name)
+	if builtinTypeNames[lower] || lower == "self" || lower == "parent" || lower == "static" {
+		return lower
+	}
+	return name
+}
+
+// ParseType parses a type string and returns type information. It accepts
+// nullable types (?T), unions (A|B), intersections (A&B) and DNF types
+// ((A&B)|C).
 func ParseType(typeStr string) *TypeInfo {
+	typeStr = strings.TrimSpace(typeStr)
 	if typeStr == "" {
 		return &TypeInfo{}
 	}
@@ -1242,44 +1858,48 @@ func ParseType(typeStr string) *TypeInfo {
 	info := &TypeInfo{}
 
 	// Check for nullable type
-	if len(typeStr) > 0 && typeStr[0] == '?' {
-		info.IsNullable = true
+	nullable := typeStr[0] == '?'
+	if nullable {
 		typeStr = typeStr[1:]
 	}
 
+	members := splitString(typeStr, "|")
+	for i, member := range members {
+		member = strings.TrimSpace(member)
+		members[i] = member
+		group := strings.TrimSuffix(strings.TrimPrefix(member, "("), ")")
+
+		var alternative []string
+		for _, name := range splitString(group, "&") {
+			alternative = append(alternative, normalizeTypeName(name))
+		}
+		if len(alternative) == 1 && alternative[0] == "iterable" {
+			info.Alternatives = append(info.Alternatives, []string{"Traversable"}, []string{"array"})
+			continue
+		}
+		info.Alternatives = append(info.Alternatives, alternative)
+	}
+	if nullable {
+		info.Alternatives = append(info.Alternatives, []string{"null"})
+	}
+
 	// Check for union type
-	if containsString(typeStr, "|") {
+	if len(members) > 1 {
 		info.IsUnion = true
-		info.UnionTypes = splitString(typeStr, "|")
-		if len(info.UnionTypes) > 0 {
-			info.BaseType = info.UnionTypes[0]
-		}
-	} else {
-		info.BaseType = typeStr
+		info.UnionTypes = members
 	}
+	info.IsIntersection = len(members) == 1 && strings.Contains(members[0], "&")
+	info.BaseType = strings.TrimPrefix(strings.TrimSpace(splitString(strings.TrimPrefix(members[0], "("), "&")[0]), "\\")
+	if IsBuiltinType(info.BaseType) {
+		info.BaseType = strings.ToLower(info.BaseType)
+	}
+	info.IsNullable = nullable || info.Accepts("null")
 
 	// Check for built-in types
-	builtinTypes := map[string]bool{
-		"int":      true,
-		"string":   true,
-		"float":    true,
-		"bool":     true,
-		"array":    true,
-		"object":   true,
-		"callable": true,
-		"iterable": true,
-		"mixed":    true,
-		"void":     true,
-		"never":    true,
-		"null":     true,
-		"false":    true,
-		"true":     true,
-	}
-
-	info.IsBuiltin = builtinTypes[info.BaseType]
+	info.IsBuiltin = builtinTypeNames[info.BaseType]
 
 	// Check for special types
-	switch info.BaseType {
+	switch strings.ToLower(info.BaseType) {
 	case "self":
 		info.IsSelf = true
 	case "parent":
@@ -1295,53 +1915,75 @@ func ParseType(typeStr string) *TypeInfo {
 	return info
 }
 
-// IsTypeCompatible checks if a value of valueType can be assigned to a variable of expectedType
-func IsTypeCompatible(expectedType, valueType string) bool {
-	if expectedType == valueType {
-		return true
+// Accepts reports whether the type has an alternative that is the single
+// type name: "int" for int|string, "null" for ?int or mixed
+func (t *TypeInfo) Accepts(name string) bool {
+	for _, alternative := range t.Alternatives {
+		if len(alternative) != 1 {
+			continue
+		}
+		if alternative[0] == name || alternative[0] == "mixed" && name != "void" && name != "never" {
+			return true
+		}
 	}
+	return false
+}
 
-	expectedInfo := ParseType(expectedType)
-	valueInfo := ParseType(valueType)
-
-	// mixed accepts any type
-	if expectedInfo.BaseType == "mixed" {
-		return true
+// String returns the type as declared, normalized: intersections in a
+// union are parenthesized and a union of one type with null is written ?T
+func (t *TypeInfo) String() string {
+	alternatives := t.Alternatives
+	if len(alternatives) == 2 && len(alternatives[0]) == 1 && alternatives[0][0] != "mixed" &&
+		alternatives[0][0] != "null" && len(alternatives[1]) == 1 && alternatives[1][0] == "null" {
+		return "?" + alternatives[0][0]
 	}
 
-	// If expected type is nullable, it accepts the non-nullable version
-	if expectedInfo.IsNullable && expectedInfo.BaseType == valueInfo.BaseType {
-		return true
+	parts := make([]string, len(alternatives))
+	for i, alternative := range alternatives {
+		parts[i] = strings.Join(alternative, "&")
+		if len(alternative) > 1 && len(alternatives) > 1 {
+			parts[i] = "(" + parts[i] + ")"
+		}
 	}
+	return strings.Join(parts, "|")
+}
 
-	// If expected type is nullable, it also accepts null
-	if expectedInfo.IsNullable && valueType == "null" {
+// IsTypeCompatible checks if a value of valueType can be assigned to a variable of expectedType.
+// Class names only match themselves (and object); checking inheritance
+// needs the class table.
+func IsTypeCompatible(expectedType, valueType string) bool {
+	if expectedType == valueType {
 		return true
 	}
 
-	// If expected is union type, check if value matches any of the union members
-	if expectedInfo.IsUnion {
-		for _, unionType := range expectedInfo.UnionTypes {
-			if unionType == valueType {
-				return true
+	expectedInfo := ParseType(expectedType)
+	valueType = normalizeTypeName(valueType)
+	valueIsClass := !IsBuiltinType(valueType)
+
+	for _, alternative := range expectedInfo.Alternatives {
+		matches := true
+		for _, member := range alternative {
+			switch {
+			case member == "mixed",
+				strings.EqualFold(member, valueType),
+				member == "object" && valueIsClass,
+				member == "bool" && (valueType == "false" || valueType == "true"):
+			default:
+				matches = false
 			}
 		}
-	}
-
-	// iterable accepts array
-	if expectedInfo.BaseType == "iterable" && valueType == "array" {
-		return true
-	}
-
-	// object accepts any class type
-	if expectedInfo.BaseType == "object" && valueInfo.IsClass {
-		return true
+		if matches {
+			return true
+		}
 	}
 
 	return false
 }
 
-// ValidatePropertyValue validates that a value matches a property's type
+// ValidatePropertyValue validates that a value matches a property's type,
+// with PHP's TypeError message when it does not. Ints are accepted for
+// float properties. Classes are matched by name only; the VM also checks
+// inheritance and coerces scalars.
 func ValidatePropertyValue(prop *PropertyDef, value *Value) error {
 	if prop.Type == "" {
 		return nil // No type constraint
@@ -1351,18 +1993,19 @@ func ValidatePropertyValue(prop *PropertyDef, value *Value) error {
 
 	// Allow null for nullable types
 	if value == nil || value.IsNull() {
-		if typeInfo.IsNullable {
+		if typeInfo.IsNullable || IsTypeCompatible(prop.Type, "null") {
 			return nil
 		}
-		return fmt.Errorf("Property %s cannot be null (type: %s)", prop.Name, prop.Type)
+		return fmt.Errorf("Cannot assign null to property $%s of type %s", prop.Name, prop.Type)
 	}
 
 	// Get value type
 	valueTypeStr := getValueTypeString(value)
 
 	// Check compatibility
-	if !IsTypeCompatible(prop.Type, valueTypeStr) {
-		return fmt.Errorf("Property %s expects type %s, got %s", prop.Name, prop.Type, valueTypeStr)
+	if !IsTypeCompatible(prop.Type, valueTypeStr) &&
+		!(valueTypeStr == "int" && IsTypeCompatible(prop.Type, "float")) {
+		return fmt.Errorf("Cannot assign %s to property $%s of type %s", valueTypeStr, prop.Name, prop.Type)
 	}
 
 	return nil
@@ -1613,6 +2256,13 @@ func (ce *ClassEntry) GetStaticConstant(name string, useLateBind bool, calledCla
 		return constant.Value, true
 	}
 
+	// Check interface constants
+	for _, iface := range ce.Interfaces {
+		if constant, exists := iface.GetConstant(name); exists {
+			return constant.Value, true
+		}
+	}
+
 	// Check parent class
 	if ce.ParentClass != nil {
 		return ce.ParentClass.GetStaticConstant(name, false, nil)
@@ -1621,6 +2271,20 @@ func (ce *ClassEntry) GetStaticConstant(name string, useLateBind bool, calledCla
 	return nil, false
 }
 
+// GetConstant retrieves a constant of the interface or of the interfaces
+// it extends
+func (ie *InterfaceEntry) GetConstant(name string) (*ClassConstant, bool) {
+	if constant, exists := ie.Constants[name]; exists {
+		return constant, true
+	}
+	for _, parent := range ie.ParentInterfaces {
+		if constant, exists := parent.GetConstant(name); exists {
+			return constant, true
+		}
+	}
+	return nil, false
+}
+
 // ============================================================================
 // Reflection API
 // ============================================================================
@@ -425,19 +425,72 @@ func TestObjectSetPropertyReadonlyUninitialized(t *testing.T) {
 		IsReadOnly: true,
 	}
 
-	// Setting uninitialized readonly property - should succeed (during construction)
-	success := obj.SetProperty("readonlyProp", NewString("value"), nil)
+	// Setting uninitialized readonly property - only from the declaring class scope
+	if obj.SetProperty("readonlyProp", NewString("value"), nil) {
+		t.Error("Expected not to initialize readonly property from global scope")
+	}
+	success := obj.SetProperty("readonlyProp", NewString("value"), class)
 	if !success {
 		t.Error("Expected to set uninitialized readonly property")
 	}
 
 	// Try to modify again - should fail
-	success = obj.SetProperty("readonlyProp", NewString("modified"), nil)
+	success = obj.SetProperty("readonlyProp", NewString("modified"), class)
 	if success {
 		t.Error("Expected not to modify readonly property after initialization")
 	}
 }
 
+func TestObjectAssignPropertyReadonlyErrors(t *testing.T) {
+	parent := NewClassEntry("Point")
+	parent.Properties["x"] = &PropertyDef{Name: "x", Type: "int", IsReadOnly: true}
+	child := NewClassEntry("Point3D")
+	if err := child.InheritFrom(parent); err != nil {
+		t.Fatalf("Unexpected inheritance error: %v", err)
+	}
+
+	tests := []struct {
+		scope *ClassEntry
+		err   string
+	}{
+		{nil, "Cannot initialize readonly property Point::$x from global scope"},
+		{child, "Cannot initialize readonly property Point::$x from scope Point3D"},
+		{parent, ""},
+		{parent, "Cannot modify readonly property Point::$x"},
+	}
+
+	obj := NewObjectFromClass(child)
+	for _, tt := range tests {
+		err := obj.AssignProperty("x", NewInt(1), tt.scope)
+		if (err == nil) != (tt.err == "") || (err != nil && err.Error() != tt.err) {
+			t.Errorf("Expected error %q, got %v", tt.err, err)
+		}
+	}
+
+	if err := obj.UnsetProperty("x", parent); err == nil || err.Error() != "Cannot unset readonly property Point::$x" {
+		t.Errorf("Expected unset error, got %v", err)
+	}
+}
+
+func TestReadonlyClass(t *testing.T) {
+	class := NewClassEntry("Money")
+	class.IsReadOnly = true
+	class.Properties["amount"] = &PropertyDef{Name: "amount", Type: "int"}
+
+	obj := NewObjectFromClass(class)
+	if !obj.Properties["amount"].IsReadOnly {
+		t.Error("Expected the properties of a readonly class to be readonly")
+	}
+	if err := obj.AssignProperty("currency", NewString("EUR"), class); err == nil || err.Error() != "Cannot create dynamic property Money::$currency" {
+		t.Errorf("Expected dynamic property error, got %v", err)
+	}
+
+	plain := NewClassEntry("Plain")
+	if err := plain.InheritFrom(class); err == nil || err.Error() != "Non-readonly class Plain cannot extend readonly class Money" {
+		t.Errorf("Expected inheritance error, got %v", err)
+	}
+}
+
 // ============================================================================
 // PropertyVisibility Tests
 // ============================================================================
@@ -2,25 +2,37 @@ package types
 
 import (
 	"fmt"
+	"runtime"
 	"sync"
+	"weak"
 )
 
 // Resource represents a PHP resource (file handle, database connection, etc.)
-// Resources are special variable types that hold references to external resources
+// Resources are special variable types that hold references to external resources.
+// The destructor runs when the resource is closed, or when the garbage
+// collector frees a resource that no script value refers to any more.
 type Resource struct {
-	id         int
-	typ        string
+	id  int
+	typ string
+	*resourceState
+}
+
+// resourceState is the mutable part of a resource. It is kept apart from
+// the Resource so the GC cleanup can release the handle without keeping
+// the Resource itself reachable.
+type resourceState struct {
 	data       interface{}
 	destructor func(interface{})
 	closed     bool
 	mutex      sync.RWMutex
 }
 
-// Global resource tracking
+// Global resource tracking. Active resources are held weakly so an
+// unreferenced resource can still be freed.
 var (
 	nextResourceID   = 1
 	resourceIDMutex  sync.Mutex
-	activeResources  = make(map[int]*Resource)
+	activeResources  = make(map[int]weak.Pointer[Resource])
 	resourcesMutex   sync.RWMutex
 	resourceRegistry = make(map[string]*ResourceType)
 	registryMutex    sync.RWMutex
@@ -62,31 +74,13 @@ func GetResourceType(name string) (*ResourceType, bool) {
 
 // NewResourceHandle creates a new resource
 func NewResourceHandle(resourceType string, data interface{}) *Resource {
-	resourceIDMutex.Lock()
-	id := nextResourceID
-	nextResourceID++
-	resourceIDMutex.Unlock()
-
 	// Get destructor from registry if available
 	var destructor func(interface{})
 	if rt, exists := GetResourceType(resourceType); exists {
 		destructor = rt.Destructor
 	}
 
-	resource := &Resource{
-		id:         id,
-		typ:        resourceType,
-		data:       data,
-		destructor: destructor,
-		closed:     false,
-	}
-
-	// Track the resource
-	resourcesMutex.Lock()
-	activeResources[id] = resource
-	resourcesMutex.Unlock()
-
-	return resource
+	return NewResourceHandleWithDestructor(resourceType, data, destructor)
 }
 
 // NewResourceHandleWithDestructor creates a resource with a custom destructor
@@ -97,20 +91,61 @@ func NewResourceHandleWithDestructor(resourceType string, data interface{}, dest
 	resourceIDMutex.Unlock()
 
 	resource := &Resource{
-		id:         id,
-		typ:        resourceType,
-		data:       data,
-		destructor: destructor,
-		closed:     false,
+		id:  id,
+		typ: resourceType,
+		resourceState: &resourceState{
+			data:       data,
+			destructor: destructor,
+		},
 	}
 
+	// Track the resource
 	resourcesMutex.Lock()
-	activeResources[id] = resource
+	activeResources[id] = weak.Make(resource)
 	resourcesMutex.Unlock()
 
+	runtime.AddCleanup(resource, freeResource, resourceCleanup{id, resource.resourceState})
+
 	return resource
 }
 
+// resourceCleanup is what the GC cleanup needs to free a resource
+type resourceCleanup struct {
+	id    int
+	state *resourceState
+}
+
+// freeResource runs the destructor of a resource the GC has collected
+// without it having been closed
+func freeResource(c resourceCleanup) {
+	c.state.close()
+
+	resourcesMutex.Lock()
+	if ptr, ok := activeResources[c.id]; ok && ptr.Value() == nil {
+		delete(activeResources, c.id)
+	}
+	resourcesMutex.Unlock()
+}
+
+// close calls the destructor once and drops the data. It reports
+// whether the state was still open.
+func (s *resourceState) close() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	if s.destructor != nil && s.data != nil {
+		s.destructor(s.data)
+	}
+
+	s.closed = true
+	s.data = nil
+	return true
+}
+
 // ============================================================================
 // Resource Properties
 // ============================================================================
@@ -120,8 +155,6 @@ func (r *Resource) ID() int {
 	if r == nil {
 		return 0
 	}
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
 	return r.id
 }
 
@@ -130,8 +163,15 @@ func (r *Resource) Type() string {
 	if r == nil {
 		return "Unknown"
 	}
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
+	return r.typ
+}
+
+// TypeName returns the type as reported by get_resource_type(): closed
+// resources have lost their type and report "Unknown"
+func (r *Resource) TypeName() string {
+	if r.IsClosed() {
+		return "Unknown"
+	}
 	return r.typ
 }
 
@@ -173,21 +213,10 @@ func (r *Resource) Close() error {
 		return fmt.Errorf("cannot close nil resource")
 	}
 
-	r.mutex.Lock()
-	defer r.mutex.Unlock()
-
-	if r.closed {
+	if !r.close() {
 		return fmt.Errorf("resource already closed")
 	}
 
-	// Call destructor if set
-	if r.destructor != nil && r.data != nil {
-		r.destructor(r.data)
-	}
-
-	r.closed = true
-	r.data = nil
-
 	// Remove from active resources
 	resourcesMutex.Lock()
 	delete(activeResources, r.id)
@@ -222,51 +251,51 @@ func GetResourceByID(id int) (*Resource, bool) {
 	resourcesMutex.RLock()
 	defer resourcesMutex.RUnlock()
 
-	res, exists := activeResources[id]
-	return res, exists
+	res := activeResources[id].Value()
+	return res, res != nil
 }
 
-// GetActiveResourceCount returns the number of active resources
-func GetActiveResourceCount() int {
+// liveResources returns the tracked resources the GC has not freed
+func liveResources() []*Resource {
 	resourcesMutex.RLock()
 	defer resourcesMutex.RUnlock()
-	return len(activeResources)
+
+	resources := make([]*Resource, 0, len(activeResources))
+	for _, ptr := range activeResources {
+		if res := ptr.Value(); res != nil {
+			resources = append(resources, res)
+		}
+	}
+	return resources
+}
+
+// GetActiveResourceCount returns the number of active resources
+func GetActiveResourceCount() int {
+	return len(liveResources())
 }
 
 // CloseAllResources closes all active resources
 // This should be called on shutdown
 func CloseAllResources() {
-	resourcesMutex.Lock()
-	resources := make([]*Resource, 0, len(activeResources))
-	for _, res := range activeResources {
-		resources = append(resources, res)
-	}
-	resourcesMutex.Unlock()
-
-	for _, res := range resources {
+	for _, res := range liveResources() {
 		res.Close()
 	}
 }
 
 // GetActiveResourceIDs returns a slice of all active resource IDs
 func GetActiveResourceIDs() []int {
-	resourcesMutex.RLock()
-	defer resourcesMutex.RUnlock()
-
-	ids := make([]int, 0, len(activeResources))
-	for id := range activeResources {
-		ids = append(ids, id)
+	resources := liveResources()
+	ids := make([]int, 0, len(resources))
+	for _, res := range resources {
+		ids = append(ids, res.id)
 	}
 	return ids
 }
 
 // GetResourcesByType returns all resources of a specific type
 func GetResourcesByType(resourceType string) []*Resource {
-	resourcesMutex.RLock()
-	defer resourcesMutex.RUnlock()
-
 	results := make([]*Resource, 0)
-	for _, res := range activeResources {
+	for _, res := range liveResources() {
 		if res.Type() == resourceType {
 			results = append(results, res)
 		}
@@ -308,7 +337,7 @@ func ResetResourceSystem() {
 	CloseAllResources()
 
 	resourcesMutex.Lock()
-	activeResources = make(map[int]*Resource)
+	activeResources = make(map[int]weak.Pointer[Resource])
 	resourcesMutex.Unlock()
 
 	registryMutex.Lock()
@@ -1,7 +1,10 @@
 package types
 
 import (
+	"runtime"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // ============================================================================
@@ -665,3 +668,68 @@ func TestResourceWithComplexData(t *testing.T) {
 		t.Error("Complex data not preserved correctly")
 	}
 }
+
+// ============================================================================
+// Garbage Collection Tests
+// ============================================================================
+
+func TestResourceFreedByGC(t *testing.T) {
+	ResetResourceSystem()
+
+	freed := make(chan interface{}, 1)
+	func() {
+		NewResourceHandleWithDestructor("stream", "handle", func(data interface{}) {
+			freed <- data
+		})
+	}()
+
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+		select {
+		case data := <-freed:
+			if data != "handle" {
+				t.Errorf("Destructor got %v, want \"handle\"", data)
+			}
+			if GetActiveResourceCount() != 0 {
+				t.Errorf("Expected no active resources, got %d", GetActiveResourceCount())
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal("Destructor was not called for an unreferenced resource")
+}
+
+func TestResourceClosedBeforeGCDestructsOnce(t *testing.T) {
+	ResetResourceSystem()
+
+	var calls atomic.Int32
+	func() {
+		res := NewResourceHandleWithDestructor("stream", "handle", func(data interface{}) {
+			calls.Add(1)
+		})
+		res.Close()
+	}()
+
+	runtime.GC()
+	time.Sleep(10 * time.Millisecond)
+	if calls.Load() != 1 {
+		t.Errorf("Destructor called %d times, want 1", calls.Load())
+	}
+}
+
+func TestResourceTypeName(t *testing.T) {
+	ResetResourceSystem()
+
+	res := NewResourceHandle("stream", nil)
+	if res.TypeName() != "stream" {
+		t.Errorf("TypeName() = %q, want \"stream\"", res.TypeName())
+	}
+	res.Close()
+	if res.TypeName() != "Unknown" {
+		t.Errorf("TypeName() after close = %q, want \"Unknown\"", res.TypeName())
+	}
+	if res.Type() != "stream" {
+		t.Errorf("Type() after close = %q, want \"stream\"", res.Type())
+	}
+}
@@ -0,0 +1,438 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// StreamResourceType is the resource type label of stream handles, as
+// reported by get_resource_type()
+const StreamResourceType = "stream"
+
+// ErrStreamNotReadable and ErrStreamNotWritable are returned when an
+// operation is not allowed by the mode the stream was opened with
+var (
+	ErrStreamNotReadable = errors.New("stream is not readable")
+	ErrStreamNotWritable = errors.New("stream is not writable")
+	ErrStreamNotSeekable = errors.New("stream does not support seeking")
+)
+
+// ============================================================================
+// Stream Modes
+// ============================================================================
+
+// StreamMode is a parsed fopen() mode string
+type StreamMode struct {
+	Mode      string
+	Read      bool
+	Write     bool
+	Append    bool
+	Create    bool
+	Truncate  bool
+	Exclusive bool
+}
+
+// ParseStreamMode parses an fopen() mode ("r", "w+", "ab", "x+t", ...).
+// The "b" and "t" flags are accepted and ignored: streams are always binary.
+func ParseStreamMode(mode string) (StreamMode, error) {
+	if mode == "" {
+		return StreamMode{}, fmt.Errorf("invalid mode %q", mode)
+	}
+	m := StreamMode{Mode: mode}
+	switch mode[0] {
+	case 'r':
+		m.Read = true
+	case 'w':
+		m.Write, m.Create, m.Truncate = true, true, true
+	case 'a':
+		m.Write, m.Create, m.Append = true, true, true
+	case 'x':
+		m.Write, m.Create, m.Exclusive = true, true, true
+	case 'c':
+		m.Write, m.Create = true, true
+	default:
+		return StreamMode{}, fmt.Errorf("invalid mode %q", mode)
+	}
+	for _, flag := range mode[1:] {
+		switch flag {
+		case '+':
+			m.Read, m.Write = true, true
+		case 'b', 't':
+		default:
+			return StreamMode{}, fmt.Errorf("invalid mode %q", mode)
+		}
+	}
+	return m, nil
+}
+
+// OpenFlags returns the os.OpenFile flags for the mode
+func (m StreamMode) OpenFlags() int {
+	flags := os.O_RDONLY
+	switch {
+	case m.Read && m.Write:
+		flags = os.O_RDWR
+	case m.Write:
+		flags = os.O_WRONLY
+	}
+	if m.Create {
+		flags |= os.O_CREATE
+	}
+	if m.Truncate {
+		flags |= os.O_TRUNC
+	}
+	if m.Exclusive {
+		flags |= os.O_EXCL
+	}
+	if m.Append {
+		flags |= os.O_APPEND
+	}
+	return flags
+}
+
+// ============================================================================
+// Streams
+// ============================================================================
+
+// Stream is a buffered, binary-safe handle behind a "stream" resource.
+// It tracks the logical position and end-of-file state the way PHP's
+// stream layer does: EOF is only reported after a read hits the end.
+type Stream struct {
+	handle io.ReadWriteCloser
+	uri    string
+	mode   StreamMode
+	buf    []byte
+	pos    int64
+	eof    bool
+
+	// Sockets and pipes return the data available instead of waiting for
+	// the whole length, and their reads block for at most timeout
+	socket   bool
+	blocking bool
+	timeout  time.Duration
+	timedOut bool
+}
+
+// DefaultSocketTimeout is the read timeout of new sockets, PHP's
+// default_socket_timeout
+const DefaultSocketTimeout = 60 * time.Second
+
+// readDeadliner is implemented by handles whose reads can time out, such
+// as network connections
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// ErrStreamWouldBlock is returned by reads of sockets that have no data
+// yet, because the stream is non-blocking or its timeout expired
+var ErrStreamWouldBlock = errors.New("stream read would block")
+
+// NewStream wraps a handle opened with the given mode
+func NewStream(handle io.ReadWriteCloser, uri string, mode StreamMode) *Stream {
+	return &Stream{handle: handle, uri: uri, mode: mode}
+}
+
+// NewSocketStream wraps a network connection in a readable and writable
+// blocking stream with the default timeout
+func NewSocketStream(handle io.ReadWriteCloser, uri string) *Stream {
+	mode, _ := ParseStreamMode("r+")
+	return &Stream{handle: handle, uri: uri, mode: mode, socket: true, blocking: true, timeout: DefaultSocketTimeout}
+}
+
+// NewPipeStream wraps an end of a pipe to another process; like sockets,
+// reads return the data available, but they wait for it indefinitely
+func NewPipeStream(handle io.ReadWriteCloser, uri string, mode StreamMode) *Stream {
+	return &Stream{handle: handle, uri: uri, mode: mode, socket: true, blocking: true}
+}
+
+// OpenFileStream opens a file with an fopen() mode string
+func OpenFileStream(path string, mode string) (*Stream, error) {
+	m, err := ParseStreamMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, m.OpenFlags(), 0666)
+	if err != nil {
+		return nil, err
+	}
+	return NewStream(file, path, m), nil
+}
+
+// NewStreamResource wraps a stream in a resource that closes it when freed
+func NewStreamResource(s *Stream) *Resource {
+	return NewResourceHandleWithDestructor(StreamResourceType, s, func(data interface{}) {
+		data.(*Stream).Close()
+	})
+}
+
+// URI returns the path or URL the stream was opened with
+func (s *Stream) URI() string { return s.uri }
+
+// Mode returns the mode the stream was opened with
+func (s *Stream) Mode() StreamMode { return s.mode }
+
+// Handle returns the underlying handle
+func (s *Stream) Handle() io.ReadWriteCloser { return s.handle }
+
+// fill reads more data into the buffer, setting EOF when there is none.
+// Socket reads return ErrStreamWouldBlock when no data arrives in time.
+func (s *Stream) fill() error {
+	if s.socket {
+		if d, ok := s.handle.(readDeadliner); ok {
+			deadline := time.Time{}
+			switch {
+			case !s.blocking:
+				deadline = time.Now().Add(time.Millisecond)
+			case s.timeout > 0:
+				deadline = time.Now().Add(s.timeout)
+			}
+			d.SetReadDeadline(deadline)
+		}
+	}
+	chunk := make([]byte, 8192)
+	n, err := s.handle.Read(chunk)
+	s.buf = append(s.buf, chunk[:n]...)
+	if n == 0 && err != nil && s.socket && isTimeout(err) {
+		s.timedOut = s.blocking
+		return ErrStreamWouldBlock
+	}
+	if n == 0 && err == nil {
+		err = io.EOF
+	}
+	if err == io.EOF {
+		s.eof = true
+		if n > 0 {
+			return nil
+		}
+	}
+	return err
+}
+
+// take consumes n bytes from the buffer
+func (s *Stream) take(n int) []byte {
+	data := append([]byte(nil), s.buf[:n]...)
+	s.buf = s.buf[n:]
+	s.pos += int64(n)
+	return data
+}
+
+// Read reads up to length bytes. It returns fewer bytes only at the end
+// of the stream.
+func (s *Stream) Read(length int) ([]byte, error) {
+	if !s.mode.Read {
+		return nil, ErrStreamNotReadable
+	}
+	s.timedOut = false
+	for len(s.buf) < length && !s.eof {
+		// Sockets return what has arrived rather than wait for more
+		if s.socket && len(s.buf) > 0 {
+			break
+		}
+		if err := s.fill(); err == ErrStreamWouldBlock {
+			break
+		} else if err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+	return s.take(min(length, len(s.buf))), nil
+}
+
+// Peek returns up to length bytes without consuming them, waiting for
+// data like Read
+func (s *Stream) Peek(length int) ([]byte, error) {
+	if !s.mode.Read {
+		return nil, ErrStreamNotReadable
+	}
+	if len(s.buf) == 0 && !s.eof {
+		if err := s.fill(); err != nil && err != io.EOF && err != ErrStreamWouldBlock {
+			return nil, err
+		}
+	}
+	return append([]byte(nil), s.buf[:min(length, len(s.buf))]...), nil
+}
+
+// ReadLine reads up to and including the next "\n", or at most
+// length-1 bytes when length is positive. It returns io.EOF when no data
+// is left.
+func (s *Stream) ReadLine(length int) ([]byte, error) {
+	if !s.mode.Read {
+		return nil, ErrStreamNotReadable
+	}
+	limit := -1
+	if length > 0 {
+		limit = length - 1
+	}
+	s.timedOut = false
+	for {
+		if i := bytes.IndexByte(s.buf, '\n'); i >= 0 && (limit < 0 || i < limit) {
+			return s.take(i + 1), nil
+		}
+		if limit >= 0 && len(s.buf) >= limit {
+			return s.take(limit), nil
+		}
+		if s.eof {
+			if len(s.buf) == 0 {
+				return nil, io.EOF
+			}
+			return s.take(len(s.buf)), nil
+		}
+		if err := s.fill(); err == ErrStreamWouldBlock {
+			// The partial line stays buffered for the next read
+			return nil, err
+		} else if err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+}
+
+// Write writes data at the current position (or the end in append mode)
+func (s *Stream) Write(data []byte) (int, error) {
+	if !s.mode.Write {
+		return 0, ErrStreamNotWritable
+	}
+	// Sockets and pipes keep read-ahead data: their reads and writes are
+	// independent
+	if len(s.buf) > 0 && !s.socket {
+		if err := s.sync(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := s.handle.Write(data)
+	s.pos += int64(n)
+	if s.mode.Append {
+		if seeker, ok := s.handle.(io.Seeker); ok {
+			if pos, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+				s.pos = pos
+			}
+		}
+	}
+	return n, err
+}
+
+// sync drops read-ahead data and moves the handle to the logical position
+func (s *Stream) sync() error {
+	seeker, ok := s.handle.(io.Seeker)
+	if !ok {
+		return ErrStreamNotSeekable
+	}
+	s.buf = nil
+	_, err := seeker.Seek(s.pos, io.SeekStart)
+	return err
+}
+
+// Seek moves the position like fseek() and clears the EOF flag
+func (s *Stream) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := s.handle.(io.Seeker)
+	if !ok {
+		return s.pos, ErrStreamNotSeekable
+	}
+	if whence == io.SeekCurrent {
+		offset, whence = s.pos+offset, io.SeekStart
+	}
+	if whence == io.SeekStart && offset < 0 {
+		return s.pos, fmt.Errorf("invalid offset %d", offset)
+	}
+	pos, err := seeker.Seek(offset, whence)
+	if err != nil {
+		return s.pos, err
+	}
+	s.buf, s.pos, s.eof = nil, pos, false
+	return pos, nil
+}
+
+// Tell returns the current position
+func (s *Stream) Tell() int64 { return s.pos }
+
+// EOF reports whether a read has reached the end of the stream
+func (s *Stream) EOF() bool { return s.eof && len(s.buf) == 0 }
+
+// Flush flushes handles that buffer writes; plain files are unbuffered
+func (s *Stream) Flush() error {
+	if flusher, ok := s.handle.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Stat returns file information for file-backed streams
+func (s *Stream) Stat() (os.FileInfo, error) {
+	if file, ok := s.handle.(*os.File); ok {
+		return file.Stat()
+	}
+	return nil, fmt.Errorf("stream does not support stat")
+}
+
+// IsSocket reports whether the stream reads like a socket: network
+// connections and pipes
+func (s *Stream) IsSocket() bool { return s.socket }
+
+// Blocking reports whether reads wait for data
+func (s *Stream) Blocking() bool { return s.blocking }
+
+// SetBlocking switches a socket between blocking and non-blocking reads;
+// other streams cannot be made non-blocking
+func (s *Stream) SetBlocking(blocking bool) bool {
+	if !s.socket {
+		return blocking
+	}
+	s.blocking = blocking
+	return true
+}
+
+// SetTimeout sets how long blocking socket reads wait for data
+func (s *Stream) SetTimeout(timeout time.Duration) bool {
+	if !s.socket {
+		return false
+	}
+	s.timeout = timeout
+	return true
+}
+
+// TimedOut reports whether the last read ended by the timeout
+func (s *Stream) TimedOut() bool { return s.timedOut }
+
+// Buffered returns the number of bytes read ahead and not consumed yet
+func (s *Stream) Buffered() int { return len(s.buf) }
+
+// WaitReadable waits until a read returns without blocking or the
+// deadline passes (a zero deadline waits indefinitely), buffering the
+// data that arrives. Streams other than sockets are always readable.
+func (s *Stream) WaitReadable(deadline time.Time) bool {
+	if len(s.buf) > 0 || s.eof {
+		return true
+	}
+	d, ok := s.handle.(readDeadliner)
+	if !s.socket || !ok {
+		return true
+	}
+	d.SetReadDeadline(deadline)
+	chunk := make([]byte, 8192)
+	n, err := s.handle.Read(chunk)
+	s.buf = append(s.buf, chunk[:n]...)
+	if n == 0 && err != nil && !isTimeout(err) {
+		s.eof = true
+	}
+	return len(s.buf) > 0 || s.eof
+}
+
+// Interrupt makes a WaitReadable in progress return
+func (s *Stream) Interrupt() {
+	if d, ok := s.handle.(readDeadliner); ok {
+		d.SetReadDeadline(time.Now())
+	}
+}
+
+// isTimeout reports whether a read failed by reaching its deadline
+func isTimeout(err error) bool {
+	var timeout interface{ Timeout() bool }
+	return errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &timeout) && timeout.Timeout())
+}
+
+// Close closes the underlying handle
+func (s *Stream) Close() error {
+	s.buf = nil
+	return s.handle.Close()
+}
@@ -0,0 +1,176 @@
+package types
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseStreamMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    StreamMode
+		wantErr bool
+	}{
+		{"r", StreamMode{Mode: "r", Read: true}, false},
+		{"rb", StreamMode{Mode: "rb", Read: true}, false},
+		{"w+", StreamMode{Mode: "w+", Read: true, Write: true, Create: true, Truncate: true}, false},
+		{"ab", StreamMode{Mode: "ab", Write: true, Create: true, Append: true}, false},
+		{"x+t", StreamMode{Mode: "x+t", Read: true, Write: true, Create: true, Exclusive: true}, false},
+		{"c", StreamMode{Mode: "c", Write: true, Create: true}, false},
+		{"", StreamMode{}, true},
+		{"q", StreamMode{}, true},
+		{"rz", StreamMode{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseStreamMode(tt.mode)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseStreamMode(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseStreamMode(%q) = %+v, want %+v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func writeStreamFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stream.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStream_ReadLineAndEOF(t *testing.T) {
+	s, err := OpenFileStream(writeStreamFile(t, "one\ntwo\nthree"), "r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, want := range []string{"one\n", "two\n", "three"} {
+		line, err := s.ReadLine(0)
+		if err != nil || string(line) != want {
+			t.Fatalf("ReadLine() = %q, %v; want %q", line, err, want)
+		}
+	}
+	if !s.EOF() {
+		t.Error("Expected EOF after reading the unterminated last line")
+	}
+	if _, err := s.ReadLine(0); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+	if s.Tell() != 13 {
+		t.Errorf("Tell() = %d, want 13", s.Tell())
+	}
+}
+
+func TestStream_EOFOnlyAfterReadingPastEnd(t *testing.T) {
+	s, err := OpenFileStream(writeStreamFile(t, "abc"), "rb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if data, _ := s.Read(3); string(data) != "abc" {
+		t.Fatalf("Read(3) = %q", data)
+	}
+	if s.EOF() {
+		t.Error("EOF should not be set before a read hits the end")
+	}
+	if data, _ := s.Read(3); len(data) != 0 {
+		t.Errorf("Read past end = %q, want empty", data)
+	}
+	if !s.EOF() {
+		t.Error("EOF should be set after a read hits the end")
+	}
+}
+
+func TestStream_ReadLineLength(t *testing.T) {
+	s, err := OpenFileStream(writeStreamFile(t, "abcdef\n"), "r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if line, _ := s.ReadLine(4); string(line) != "abc" {
+		t.Errorf("ReadLine(4) = %q, want %q", line, "abc")
+	}
+	if line, _ := s.ReadLine(0); string(line) != "def\n" {
+		t.Errorf("ReadLine(0) = %q, want %q", line, "def\n")
+	}
+}
+
+func TestStream_SeekAndWrite(t *testing.T) {
+	path := writeStreamFile(t, "hello world")
+	s, err := OpenFileStream(path, "r+")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Read(5)
+	if _, err := s.Write([]byte(",\x00")); err != nil {
+		t.Fatal(err)
+	}
+	if s.Tell() != 7 {
+		t.Errorf("Tell() after write = %d, want 7", s.Tell())
+	}
+	if pos, err := s.Seek(-2, io.SeekEnd); err != nil || pos != 9 {
+		t.Errorf("Seek(-2, SeekEnd) = %d, %v", pos, err)
+	}
+	if data, _ := s.Read(10); string(data) != "ld" {
+		t.Errorf("Read after seek = %q, want %q", data, "ld")
+	}
+	if _, err := s.Seek(-1, io.SeekStart); err == nil {
+		t.Error("Expected an error seeking before the start")
+	}
+	s.Close()
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "hello,\x00orld" {
+		t.Errorf("File contents = %q", data)
+	}
+}
+
+func TestStream_ModeRestrictions(t *testing.T) {
+	path := writeStreamFile(t, "data")
+
+	r, _ := OpenFileStream(path, "r")
+	defer r.Close()
+	if _, err := r.Write([]byte("x")); err != ErrStreamNotWritable {
+		t.Errorf("Expected ErrStreamNotWritable, got %v", err)
+	}
+
+	a, _ := OpenFileStream(path, "a")
+	defer a.Close()
+	if _, err := a.Read(1); err != ErrStreamNotReadable {
+		t.Errorf("Expected ErrStreamNotReadable, got %v", err)
+	}
+	a.Write([]byte("!"))
+	if a.Tell() != 5 {
+		t.Errorf("Append Tell() = %d, want 5", a.Tell())
+	}
+
+	if _, err := OpenFileStream(path, "x"); err == nil {
+		t.Error("Expected mode x to fail on an existing file")
+	}
+}
+
+func TestNewStreamResource_ClosesStream(t *testing.T) {
+	s, err := OpenFileStream(writeStreamFile(t, "data"), "r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := NewStreamResource(s)
+	if res.Type() != StreamResourceType {
+		t.Errorf("Type() = %q, want %q", res.Type(), StreamResourceType)
+	}
+	res.Close()
+	if _, err := s.Read(1); err == nil {
+		t.Error("Expected the stream to be closed with its resource")
+	}
+}
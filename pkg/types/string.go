@@ -3,6 +3,8 @@ package types
 import (
 	"hash/fnv"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // String represents a PHP string value
@@ -12,6 +14,7 @@ type String struct {
 	len    int    // Cached length in bytes
 	hash   uint64 // Cached hash value (0 means not computed)
 	interned bool // Whether this string is interned (deduplicated)
+	lower  atomic.Pointer[String] // Cached lower-case form of an interned string
 }
 
 // NewPhpString creates a new PHP string
@@ -249,25 +252,59 @@ func (s *String) ContainsNull() bool {
 // String Interning (for optimization)
 // ============================================================================
 
-// stringInternMap is a global map for string interning
-// This is used to deduplicate common strings like property names
-var stringInternMap = make(map[string]*String)
+// atoms is the table of interned strings shared by the compiler and every
+// VM: the literals and names of compiled code. It is safe for concurrent
+// use, as parallel runtimes intern into the same table.
+var atoms = struct {
+	sync.RWMutex
+	strings map[string]*String
+}{strings: make(map[string]*String)}
 
 // Intern returns an interned version of the string
 // Multiple calls with the same value will return the same String instance
 // This saves memory for frequently used strings
 func Intern(s string) *String {
-	if interned, exists := stringInternMap[s]; exists {
+	atoms.RLock()
+	interned, exists := atoms.strings[s]
+	atoms.RUnlock()
+	if exists {
 		return interned
 	}
 
+	atoms.Lock()
+	defer atoms.Unlock()
+	if interned, exists := atoms.strings[s]; exists {
+		return interned
+	}
 	str := NewPhpString(s)
 	str.interned = true
 	str.hash = str.computeHash() // Compute hash eagerly for interned strings
-	stringInternMap[s] = str
+	atoms.strings[s] = str
 	return str
 }
 
+// InternString returns the interned copy of s. Interned copies of equal
+// strings share their bytes, so comparing them, as map lookups keyed by
+// names do, stops at the pointer check.
+func InternString(s string) string {
+	return Intern(s).val
+}
+
+// Lower returns the lower-case form of the string, as used for the keys of
+// case-insensitive names. For an interned string the form is interned too
+// and computed only once.
+func (s *String) Lower() *String {
+	if !s.interned {
+		return s.ToLower()
+	}
+	if lower := s.lower.Load(); lower != nil {
+		return lower
+	}
+	lower := Intern(strings.ToLower(s.val))
+	s.lower.Store(lower)
+	return lower
+}
+
 // IsInterned returns true if this string is interned
 func (s *String) IsInterned() bool {
 	return s.interned
@@ -1,7 +1,9 @@
 package types
 
 import (
+	"sync"
 	"testing"
+	"unsafe"
 )
 
 func TestNewPhpString(t *testing.T) {
@@ -384,3 +386,42 @@ func TestBinarySafety(t *testing.T) {
 		}
 	}
 }
+
+func TestInternString(t *testing.T) {
+	a := InternString(string([]byte("atom")))
+	b := InternString(string([]byte("atom")))
+	if unsafe.StringData(a) != unsafe.StringData(b) {
+		t.Error("Interned copies of equal strings should share their bytes")
+	}
+
+	var wg sync.WaitGroup
+	interned := make([]*String, 8)
+	for i := range interned {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			interned[i] = Intern("concurrent_atom")
+		}(i)
+	}
+	wg.Wait()
+	for _, s := range interned[1:] {
+		if s != interned[0] {
+			t.Fatal("Concurrent interning should return the same instance")
+		}
+	}
+}
+
+func TestLower(t *testing.T) {
+	name := Intern("StrLen")
+	lower := name.Lower()
+	if lower.Val() != "strlen" || !lower.IsInterned() {
+		t.Errorf("Expected interned \"strlen\", got %q", lower.Val())
+	}
+	if name.Lower() != lower || lower != Intern("strlen") {
+		t.Error("The lower-case form of an interned string should be cached")
+	}
+
+	if got := NewPhpString("ABC").Lower(); got.Val() != "abc" || got.IsInterned() {
+		t.Errorf("Expected a plain \"abc\", got %q", got.Val())
+	}
+}
@@ -410,6 +410,69 @@ func TestTrait_AbstractMethod(t *testing.T) {
 	}
 }
 
+func TestTrait_AbstractMethodImplementedElsewhere(t *testing.T) {
+	abstract := NewTraitEntry("Named")
+	abstract.Methods["name"] = &MethodDef{Name: "name", IsAbstract: true, DeclaringClass: "Named"}
+	concrete := NewTraitEntry("Greets")
+	concrete.Methods["name"] = &MethodDef{Name: "name", DeclaringClass: "Greets"}
+
+	// Another trait implements the abstract method
+	class := NewClassEntry("MyClass")
+	class.Traits = []*TraitEntry{abstract, concrete}
+	if err := class.ApplyTraits(); err != nil {
+		t.Fatalf("ApplyTraits failed: %v", err)
+	}
+	if class.Methods["name"].DeclaringClass != "Greets" {
+		t.Errorf("Expected name() from Greets, got %s", class.Methods["name"].DeclaringClass)
+	}
+
+	// The parent class implements the abstract method
+	parent := NewClassEntry("Base")
+	parent.Methods["name"] = &MethodDef{Name: "name", DeclaringClass: "Base"}
+	class = NewClassEntry("Child")
+	class.ParentClass = parent
+	class.Traits = []*TraitEntry{abstract}
+	if err := class.ApplyTraits(); err != nil {
+		t.Fatalf("ApplyTraits failed: %v", err)
+	}
+	if _, exists := class.Methods["name"]; exists {
+		t.Error("Expected the abstract trait method not to shadow the inherited one")
+	}
+}
+
+func TestTrait_UnqualifiedAliasAndConstants(t *testing.T) {
+	trait1 := NewTraitEntry("Trait1")
+	trait1.Methods["hello"] = &MethodDef{Name: "hello", DeclaringClass: "Trait1"}
+	trait1.Constants["VERSION"] = &ClassConstant{Name: "VERSION", Value: NewInt(1)}
+	trait2 := NewTraitEntry("Trait2")
+	trait2.Methods["world"] = &MethodDef{Name: "world", DeclaringClass: "Trait2"}
+
+	class := NewClassEntry("MyClass")
+	class.Traits = []*TraitEntry{trait1, trait2}
+	class.TraitAliases["greet"] = "::hello:protected"
+	if err := class.ApplyTraits(); err != nil {
+		t.Fatalf("ApplyTraits failed: %v", err)
+	}
+	if greet := class.Methods["greet"]; greet == nil || greet.Visibility != VisibilityProtected {
+		t.Error("Expected protected alias greet() of Trait1::hello()")
+	}
+	if _, ok := class.Constants["VERSION"]; !ok {
+		t.Error("Expected trait constant VERSION")
+	}
+
+	// An unqualified alias of a method in several traits is ambiguous
+	trait2.Methods["hello"] = &MethodDef{Name: "hello", DeclaringClass: "Trait2"}
+	class = NewClassEntry("MyClass")
+	class.Traits = []*TraitEntry{trait1, trait2}
+	class.TraitPrecedence["hello"] = "Trait1"
+	class.TraitAliases["greet"] = "::hello"
+	err := class.ApplyTraits()
+	want := "An alias was defined for method hello(), which exists in both Trait1 and Trait2. Use Trait1::hello or Trait2::hello to resolve the ambiguity"
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q, got %v", want, err)
+	}
+}
+
 // ============================================================================
 // Static Methods in Traits
 // ============================================================================
@@ -315,6 +315,43 @@ func TestTypeCheck_UnionTypeCompatibility(t *testing.T) {
 	}
 }
 
+func TestTypeCheck_IntersectionAndDNFTypes(t *testing.T) {
+	tests := []struct {
+		decl         string
+		alternatives int
+		nullable     bool
+		str          string
+	}{
+		{"Countable&Traversable", 1, false, "Countable&Traversable"},
+		{"(A&B)|C", 2, false, "(A&B)|C"},
+		{"(A&B)|null", 2, true, "(A&B)|null"},
+		{"?int", 2, true, "?int"},
+		{"int|null", 2, true, "?int"},
+		{"INT|String", 2, false, "int|string"},
+		{"iterable", 2, false, "Traversable|array"},
+		{"null", 1, true, "null"},
+	}
+
+	for _, tt := range tests {
+		info := ParseType(tt.decl)
+		if len(info.Alternatives) != tt.alternatives || info.IsNullable != tt.nullable || info.String() != tt.str {
+			t.Errorf("ParseType(%q): got %d alternatives, nullable %v, %q", tt.decl,
+				len(info.Alternatives), info.IsNullable, info.String())
+		}
+	}
+
+	if !ParseType("A&B").IsIntersection || ParseType("(A&B)|C").IsIntersection {
+		t.Error("Only a single group of class types is an intersection")
+	}
+	// A class name alone is not known to implement every member of A&B
+	if !IsTypeCompatible("(A&B)|int", "int") || IsTypeCompatible("(A&B)|int", "A") || IsTypeCompatible("A&B", "int") {
+		t.Error("DNF type compatibility is wrong")
+	}
+	if !IsTypeCompatible("bool", "false") || IsTypeCompatible("false", "int") {
+		t.Error("false should be a bool")
+	}
+}
+
 // ============================================================================
 // Type Validation Tests
 // ============================================================================
@@ -337,6 +374,20 @@ func TestTypeCheck_ValidatePropertyType(t *testing.T) {
 	if err == nil {
 		t.Fatal("string value should be invalid for int property")
 	}
+	if want := "Cannot assign string to property $age of type int"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err.Error(), want)
+	}
+
+	// Invalid: null for non-nullable property
+	err = ValidatePropertyValue(prop, nil)
+	if err == nil || err.Error() != "Cannot assign null to property $age of type int" {
+		t.Errorf("null should be invalid for int property, got %v", err)
+	}
+
+	// Valid: int value for float property
+	if err := ValidatePropertyValue(&PropertyDef{Name: "ratio", Type: "float"}, NewInt(1)); err != nil {
+		t.Errorf("int value should be valid for float property: %v", err)
+	}
 }
 
 func TestTypeCheck_ValidateNullableProperty(t *testing.T) {
@@ -2,7 +2,6 @@ package types
 
 import (
 	"fmt"
-	"math"
 	"strconv"
 	"strings"
 )
@@ -45,6 +44,27 @@ type Value struct {
 // Type Constructors
 // ============================================================================
 
+// Null, the booleans and the small integers are shared values created once
+// instead of on every call. Sharing is safe as a value is never modified in
+// place; only a reference is, and it is always allocated by NewReference.
+const (
+	minCachedInt = -256
+	maxCachedInt = 1024
+)
+
+var (
+	nullValue  = &Value{typ: TypeNull}
+	trueValue  = &Value{typ: TypeBool, data: true}
+	falseValue = &Value{typ: TypeBool, data: false}
+	smallInts  [maxCachedInt - minCachedInt + 1]Value
+)
+
+func init() {
+	for i := range smallInts {
+		smallInts[i] = Value{typ: TypeInt, data: int64(i + minCachedInt)}
+	}
+}
+
 // NewUndef creates an undefined value
 func NewUndef() *Value {
 	return &Value{typ: TypeUndef}
@@ -52,16 +72,22 @@ func NewUndef() *Value {
 
 // NewNull creates a null value
 func NewNull() *Value {
-	return &Value{typ: TypeNull}
+	return nullValue
 }
 
 // NewBool creates a boolean value
 func NewBool(v bool) *Value {
-	return &Value{typ: TypeBool, data: v}
+	if v {
+		return trueValue
+	}
+	return falseValue
 }
 
 // NewInt creates an integer value
 func NewInt(v int64) *Value {
+	if v >= minCachedInt && v <= maxCachedInt {
+		return &smallInts[v-minCachedInt]
+	}
 	return &Value{typ: TypeInt, data: v}
 }
 
@@ -267,8 +293,8 @@ func (v *Value) ToBool() bool {
 	case TypeInt:
 		return v.data.(int64) != 0
 	case TypeFloat:
-		f := v.data.(float64)
-		return f != 0.0 && !math.IsNaN(f)
+		// NAN is true, like any other non-zero float
+		return v.data.(float64) != 0.0
 	case TypeString:
 		s := v.data.(string)
 		// Empty string and "0" are false
@@ -307,20 +333,8 @@ func (v *Value) ToString() string {
 	case TypeInt:
 		return strconv.FormatInt(v.data.(int64), 10)
 	case TypeFloat:
-		f := v.data.(float64)
-		// Format float like PHP does
-		if math.IsNaN(f) {
-			return "NAN"
-		}
-		if math.IsInf(f, 1) {
-			return "INF"
-		}
-		if math.IsInf(f, -1) {
-			return "-INF"
-		}
-		// Remove trailing zeros
-		s := strconv.FormatFloat(f, 'f', -1, 64)
-		return s
+		// Format float like PHP does, with the precision ini setting
+		return FormatFloat(v.data.(float64), Precision())
 	case TypeString:
 		return v.data.(string)
 	case TypeArray:
@@ -420,10 +434,9 @@ func (v *Value) Copy() *Value {
 	case TypeString:
 		copied.data = v.data.(string)
 	case TypeArray:
-		// Arrays use copy-on-write (COW) semantics
-		// For now, just reference the same array
-		// TODO: Implement proper COW in Phase 7
-		copied.data = v.data.(*Array)
+		// Arrays use copy-on-write (COW) semantics: the copy shares
+		// storage with the original until one of them is modified
+		copied.data = v.data.(*Array).Copy()
 	case TypeObject:
 		// Objects are passed by reference in PHP
 		copied.data = v.data.(*Object)
@@ -482,103 +495,41 @@ func (v *Value) Deref() *Value {
 	return v.data.(*Value).Deref()
 }
 
+// Assign replaces the value a reference points to, which is how functions
+// write to by-reference parameters. It reports false when v is not a
+// reference.
+func (v *Value) Assign(value *Value) bool {
+	if v == nil || v.typ != TypeReference {
+		return false
+	}
+	if target := v.data.(*Value); target != nil && target.typ == TypeReference {
+		return target.Assign(value)
+	}
+	value = value.Deref()
+	AddRef(value)
+	DelRef(v.data.(*Value))
+	v.data = value
+	return true
+}
+
 // ============================================================================
 // Equality and Comparison
 // ============================================================================
 
-// Equals checks loose equality (==)
+// Equals checks loose equality (==), see LooseEquals
 func (v *Value) Equals(other *Value) bool {
-	if v == nil && other == nil {
-		return true
-	}
 	if v == nil || other == nil {
 		return v.IsNull() && other.IsNull()
 	}
-
-	// Dereference if needed
-	v = v.Deref()
-	other = other.Deref()
-
-	// Same type comparison
-	if v.typ == other.typ {
-		switch v.typ {
-		case TypeUndef, TypeNull:
-			return true
-		case TypeBool:
-			return v.data.(bool) == other.data.(bool)
-		case TypeInt:
-			return v.data.(int64) == other.data.(int64)
-		case TypeFloat:
-			return v.data.(float64) == other.data.(float64)
-		case TypeString:
-			return v.data.(string) == other.data.(string)
-		case TypeArray:
-			// Array equality is complex - implement later
-			return v.data.(*Array) == other.data.(*Array)
-		case TypeObject:
-			// Object equality is identity
-			return v.data.(*Object) == other.data.(*Object)
-		case TypeResource:
-			return v.data.(*Resource) == other.data.(*Resource)
-		}
-	}
-
-	// Type juggling for loose comparison
-	// PHP converts to numeric if one side is numeric
-	if v.IsScalar() && other.IsScalar() {
-		// Try numeric comparison
-		if (v.typ == TypeInt || v.typ == TypeFloat) || (other.typ == TypeInt || other.typ == TypeFloat) {
-			return v.ToFloat() == other.ToFloat()
-		}
-		// String comparison
-		return v.ToString() == other.ToString()
-	}
-
-	return false
+	return LooseEquals(v, other)
 }
 
-// Identical checks strict equality (===)
+// Identical checks strict equality (===), see StrictEquals
 func (v *Value) Identical(other *Value) bool {
-	if v == nil && other == nil {
-		return true
-	}
 	if v == nil || other == nil {
-		return false
-	}
-
-	// Type must match
-	if v.typ != other.typ {
-		return false
+		return v == nil && other == nil
 	}
-
-	// Dereference
-	v = v.Deref()
-	other = other.Deref()
-
-	// Check data
-	switch v.typ {
-	case TypeUndef, TypeNull:
-		return true
-	case TypeBool:
-		return v.data.(bool) == other.data.(bool)
-	case TypeInt:
-		return v.data.(int64) == other.data.(int64)
-	case TypeFloat:
-		return v.data.(float64) == other.data.(float64)
-	case TypeString:
-		return v.data.(string) == other.data.(string)
-	case TypeArray:
-		// Arrays must have identical keys and values
-		// TODO: Implement proper array identity check
-		return v.data.(*Array) == other.data.(*Array)
-	case TypeObject:
-		// Object identity is reference equality
-		return v.data.(*Object) == other.data.(*Object)
-	case TypeResource:
-		return v.data.(*Resource) == other.data.(*Resource)
-	}
-
-	return false
+	return StrictEquals(v, other)
 }
 
 // ============================================================================
@@ -659,6 +610,32 @@ func (v *Value) TypeString() string {
 	}
 }
 
+// TypeName returns the type as written in type declarations and error
+// messages ("int", "float", "null", ...); objects are named by their class
+func (v *Value) TypeName() string {
+	v = v.Deref()
+	switch v.Type() {
+	case TypeUndef, TypeNull:
+		return "null"
+	case TypeBool:
+		return "bool"
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeString:
+		return "string"
+	case TypeArray:
+		return "array"
+	case TypeObject:
+		return v.ToObject().ClassName
+	case TypeResource:
+		return "resource"
+	default:
+		return "mixed"
+	}
+}
+
 // ============================================================================
 // Helper Functions
 // ============================================================================
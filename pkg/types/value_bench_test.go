@@ -0,0 +1,29 @@
+package types
+
+import (
+	"testing"
+)
+
+// BenchmarkCountingLoop benchmarks the values a for ($i = 0; $i < 1000;
+// $i++) loop creates: a comparison and an increment per iteration
+func BenchmarkCountingLoop(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		counter := NewInt(0)
+		for NewBool(counter.ToInt() < 1000).ToBool() {
+			counter = NewInt(counter.ToInt() + 1)
+		}
+	}
+}
+
+// BenchmarkLargeIntArithmetic benchmarks the same loop on integers past
+// the cached range, which are still allocated
+func BenchmarkLargeIntArithmetic(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		counter := NewInt(1 << 20)
+		for NewBool(counter.ToInt() < 1<<20+1000).ToBool() {
+			counter = NewInt(counter.ToInt() + 1)
+		}
+	}
+}
@@ -261,8 +261,8 @@ func TestToBool_PHPTruthiness(t *testing.T) {
 
 func TestToBool_NaN(t *testing.T) {
 	v := NewFloat(math.NaN())
-	if v.ToBool() {
-		t.Error("NaN should be falsy")
+	if !v.ToBool() {
+		t.Error("NaN should be truthy, as (bool)NAN is true in PHP")
 	}
 }
 
@@ -425,6 +425,21 @@ func TestDeref(t *testing.T) {
 	}
 }
 
+func TestAssignThroughReference(t *testing.T) {
+	ref := NewReference(NewInt(1))
+	outer := NewReference(ref)
+
+	if !outer.Assign(NewReference(NewString("x"))) {
+		t.Fatal("Assign on a reference should succeed")
+	}
+	if ref.Deref().ToString() != "x" || outer.Deref().ToString() != "x" {
+		t.Errorf("Expected both references to see the new value, got %v", ref.Deref())
+	}
+	if NewInt(1).Assign(NewInt(2)) {
+		t.Error("Assign on a non-reference should fail")
+	}
+}
+
 // ============================================================================
 // Equality Tests
 // ============================================================================
@@ -1091,3 +1106,26 @@ func TestArray_OtherKeyType(t *testing.T) {
 		t.Errorf("Expected 42, got %d", val.ToInt())
 	}
 }
+
+func TestCachedValues(t *testing.T) {
+	if NewNull() != NewNull() || NewBool(true) != NewBool(true) || NewBool(false) != NewBool(false) {
+		t.Error("null and the booleans should be shared")
+	}
+	for _, n := range []int64{minCachedInt, -1, 0, 1, maxCachedInt} {
+		if NewInt(n) != NewInt(n) || NewInt(n).ToInt() != n {
+			t.Errorf("NewInt(%d) should return the cached %d", n, n)
+		}
+	}
+	for _, n := range []int64{minCachedInt - 1, maxCachedInt + 1} {
+		if NewInt(n) == NewInt(n) || NewInt(n).ToInt() != n {
+			t.Errorf("NewInt(%d) should allocate %d", n, n)
+		}
+	}
+
+	// A reference to a cached value replaces its target, never the value
+	ref := NewReference(NewInt(3))
+	ref.Assign(NewInt(4))
+	if NewInt(3).ToInt() != 3 {
+		t.Error("Assigning through a reference changed a cached value")
+	}
+}
@@ -0,0 +1,293 @@
+package vm
+
+import (
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Named Arguments and Argument Unpacking
+// ============================================================================
+
+// namedArg is an argument passed by parameter name: f(name: $value)
+type namedArg struct {
+	name  string
+	value *types.Value
+}
+
+// addNamedArgument adds a named argument to a call being prepared
+func (vm *VM) addNamedArgument(params *CallParams, name string, value *types.Value) error {
+	for _, arg := range params.named {
+		if arg.name == name {
+			return vm.ThrowError("Error", "Named parameter $%s overwrites previous argument", name)
+		}
+	}
+	params.named = append(params.named, namedArg{name: name, value: value})
+	return nil
+}
+
+// unpackArguments adds the elements of an unpacked array (...$args) to a
+// call being prepared: integer keys as positional arguments, string keys
+// as named ones
+func (vm *VM) unpackArguments(params *CallParams, value *types.Value) error {
+	value = value.Deref()
+	if !value.IsArray() {
+		return vm.ThrowError("Error", "Only arrays and Traversables can be unpacked")
+	}
+
+	var err error
+	value.ToArray().Each(func(key, element *types.Value) bool {
+		element = assignValue(element.Deref())
+		if key.IsString() {
+			err = vm.addNamedArgument(params, key.ToString(), element)
+			return err == nil
+		}
+		if len(params.named) > 0 {
+			err = vm.ThrowError("Error", "Cannot use positional argument after named argument during unpacking")
+			return false
+		}
+		params.params = append(params.params, element)
+		return true
+	})
+	return err
+}
+
+// opSendUnpack sends the elements of an array as arguments: f(...$args)
+// Op1: array to unpack
+func (vm *VM) opSendUnpack(frame *Frame, instr Instruction) error {
+	value, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	if frame.pendingParams == nil {
+		frame.pendingParams = &CallParams{}
+	}
+	return vm.unpackArguments(frame.pendingParams, value)
+}
+
+// bindArguments returns the positional argument list of a call, with each
+// named argument moved to the position of its parameter. Parameters
+// skipped by named arguments take their default value. Functions without
+// parameter definitions (defs nil), such as most builtins, only accept
+// positional arguments. Named arguments matching no parameter are returned
+// apart when the function has a variadic parameter, which collects them
+// under their names.
+func (vm *VM) bindArguments(name string, defs []*types.ParameterDef, params *CallParams) ([]*types.Value, []namedArg, error) {
+	if params == nil {
+		return []*types.Value{}, nil, nil
+	}
+	if len(params.named) == 0 {
+		return params.params, nil, nil
+	}
+
+	args := append([]*types.Value(nil), params.params...)
+	var rest []namedArg
+	for _, arg := range params.named {
+		position := -1
+		variadic := false
+		for i, def := range defs {
+			if def.IsVariadic {
+				variadic = true
+			} else if def.Name == arg.name {
+				position = i
+				break
+			}
+		}
+		if position < 0 && variadic {
+			rest = append(rest, arg)
+			continue
+		}
+		if position < 0 {
+			return nil, nil, vm.ThrowError("Error", "Unknown named parameter $%s", arg.name)
+		}
+		if position < len(args) && args[position] != nil {
+			return nil, nil, vm.ThrowError("Error", "Named parameter $%s overwrites previous argument", arg.name)
+		}
+		for len(args) <= position {
+			args = append(args, nil)
+		}
+		args[position] = arg.value
+	}
+
+	// Fill the parameters skipped over with their defaults
+	for i, arg := range args {
+		if arg != nil {
+			continue
+		}
+		if !defs[i].HasDefault {
+			return nil, nil, vm.ThrowError("ArgumentCountError", "%s(): Argument #%d ($%s) not passed", name, i+1, defs[i].Name)
+		}
+		args[i] = types.NewNull()
+		if defs[i].Default != nil {
+			args[i] = assignValue(defs[i].Default)
+		}
+	}
+	return args, rest, nil
+}
+
+// ============================================================================
+// By-Reference Arguments
+// ============================================================================
+
+// builtinRefParams lists the parameters Go functions take by reference
+// (array &$array, &$matches), by position
+var builtinRefParams = map[string][]int{
+	"sort":                   {0},
+	"rsort":                  {0},
+	"usort":                  {0},
+	"uasort":                 {0},
+	"uksort":                 {0},
+	"asort":                  {0},
+	"arsort":                 {0},
+	"ksort":                  {0},
+	"krsort":                 {0},
+	"natsort":                {0},
+	"natcasesort":            {0},
+	"shuffle":                {0},
+	"array_walk":             {0},
+	"preg_match":             {2},
+	"preg_match_all":         {2},
+	"preg_replace":           {4},
+	"preg_replace_callback":  {4},
+	"exec":                   {1, 2},
+	"system":                 {1},
+	"passthru":               {1},
+	"proc_open":              {2},
+	"fsockopen":              {2, 3},
+	"pfsockopen":             {2, 3},
+	"stream_socket_client":   {1, 2},
+	"stream_socket_server":   {1, 2},
+	"stream_socket_accept":   {2},
+	"stream_socket_recvfrom": {3},
+	"stream_select":          {0, 1, 2},
+	"similar_text":           {2},
+	"headers_sent":           {0, 1},
+	"settype":                {0},
+}
+
+// builtinRefVariadic gives the first parameter of the Go functions taking
+// a variadic list by reference (mixed &...$vars)
+var builtinRefVariadic = map[string]int{
+	"array_multisort": 0,
+	"sscanf":          2,
+}
+
+// BuiltinTakesRefs reports whether a Go function takes any argument by
+// reference
+func BuiltinTakesRefs(name string) bool {
+	name = strings.ToLower(strings.TrimPrefix(name, "\\"))
+	_, variadic := builtinRefVariadic[name]
+	return len(builtinRefParams[name]) > 0 || variadic
+}
+
+// BuiltinTakesRef reports whether a Go function takes the argument at a
+// position by reference
+func BuiltinTakesRef(name string, position int) bool {
+	name = strings.ToLower(strings.TrimPrefix(name, "\\"))
+	for _, p := range builtinRefParams[name] {
+		if p == position {
+			return true
+		}
+	}
+	from, ok := builtinRefVariadic[name]
+	return ok && position >= from
+}
+
+// paramByRef reports whether a parameter is taken by reference: the one
+// at a position, or the one named for a named argument
+func paramByRef(params []*types.ParameterDef, position int, name string) bool {
+	if name != "" {
+		for _, param := range params {
+			if param.Name == name {
+				return param.PassedByRef
+			}
+		}
+		return false
+	}
+	if position < len(params) {
+		return params[position].PassedByRef
+	}
+	last := len(params) - 1
+	return last >= 0 && params[last].IsVariadic && params[last].PassedByRef
+}
+
+// pendingArgByRef reports whether the call being prepared takes its next
+// argument, or the one named, by reference
+func (vm *VM) pendingArgByRef(frame *Frame, name string) bool {
+	position := 0
+	if frame.pendingParams != nil {
+		position = len(frame.pendingParams.params)
+	}
+	switch {
+	case frame.pendingCall != nil:
+		target := frame.pendingCall
+		if target.Builtin != nil && name == "" && BuiltinTakesRef(target.Name, position) {
+			return true
+		}
+		return paramByRef(target.parameters(), position, name)
+	case frame.pendingMethod != nil:
+		return paramByRef(frame.pendingMethod.Parameters, position, name)
+	case frame.pendingFunction != nil:
+		return paramByRef(frame.pendingFunction.Parameters, position, name)
+	}
+	return false
+}
+
+// variableReference returns the reference a variable is bound to, binding
+// the variable to a new one first. A temporary holds a reference fetched
+// from an element (FETCH_LIST_W); any other value is wrapped in a
+// reference of its own.
+func (vm *VM) variableReference(frame *Frame, op Operand) (*types.Value, error) {
+	if op.Type == OpCV {
+		slot := frame.getLocal(int(op.Value))
+		if !slot.IsReference() {
+			value := slot
+			if value.IsUndef() {
+				value = types.NewNull()
+			}
+			slot = types.NewReference(value)
+			frame.setLocal(int(op.Value), slot)
+		}
+		return slot, nil
+	}
+	value, err := vm.getOperandValue(frame, op)
+	if err != nil {
+		return nil, err
+	}
+	if !value.IsReference() {
+		value = types.NewReference(assignValue(value))
+	}
+	return value, nil
+}
+
+// opSendRef sends an argument by reference: the variable and the
+// parameter share a reference
+// Op1: variable, or a temporary holding a reference
+// Op2: parameter name for a named argument (unused for positional ones)
+func (vm *VM) opSendRef(frame *Frame, instr Instruction) error {
+	ref, err := vm.variableReference(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	return vm.sendArgument(frame, instr.Op2, ref)
+}
+
+// opSendVarEx sends a variable to a function not known when compiling:
+// by reference when it takes the parameter by reference, else by value
+// Op1: variable
+// Op2: parameter name for a named argument (unused for positional ones)
+func (vm *VM) opSendVarEx(frame *Frame, instr Instruction) error {
+	name := ""
+	if instr.Op2.Type != OpUnused {
+		value, err := vm.getOperandValue(frame, instr.Op2)
+		if err != nil {
+			return err
+		}
+		name = value.ToString()
+	}
+	if vm.pendingArgByRef(frame, name) {
+		return vm.opSendRef(frame, instr)
+	}
+	return vm.opSendVal(frame, instr)
+}
@@ -0,0 +1,157 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// paramsFunction returns a function with parameters $a, $b = 2 and $c = 3
+func paramsFunction() *CompiledFunction {
+	return &CompiledFunction{
+		Name:      "f",
+		NumParams: 3,
+		NumLocals: 10,
+		Parameters: []*types.ParameterDef{
+			{Name: "a"},
+			{Name: "b", HasDefault: true, Default: types.NewInt(2)},
+			{Name: "c", HasDefault: true, Default: types.NewInt(3)},
+		},
+	}
+}
+
+func TestBindArguments(t *testing.T) {
+	vm := New()
+	fn := paramsFunction()
+
+	// f(1, c: 30): $b takes its default
+	params := &CallParams{params: []*types.Value{types.NewInt(1)}}
+	if err := vm.addNamedArgument(params, "c", types.NewInt(30)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	args, rest, err := vm.bindArguments("f", fn.Parameters, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(args) != 3 || args[0].ToInt() != 1 || args[1].ToInt() != 2 || args[2].ToInt() != 30 || rest != nil {
+		t.Errorf("Expected [1 2 30], got %v", args)
+	}
+
+	// A variadic parameter takes the unknown names: f(1, d: 4, b: 20)
+	variadic := append(fn.Parameters, &types.ParameterDef{Name: "rest", IsVariadic: true})
+	args, rest, err = vm.bindArguments("f", variadic, &CallParams{
+		params: []*types.Value{types.NewInt(1)},
+		named:  []namedArg{{"d", types.NewInt(4)}, {"b", types.NewInt(20)}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(args) != 2 || args[1].ToInt() != 20 || len(rest) != 1 || rest[0].name != "d" {
+		t.Errorf("Expected [1 20] and $d apart, got %v %v", args, rest)
+	}
+
+	errorTests := []struct {
+		positional []*types.Value
+		named      []namedArg
+		class      string
+		message    string
+	}{
+		{nil, []namedArg{{"d", types.NewInt(1)}}, "Error", "Unknown named parameter $d"},
+		{[]*types.Value{types.NewInt(1)}, []namedArg{{"a", types.NewInt(1)}}, "Error", "Named parameter $a overwrites previous argument"},
+		{nil, []namedArg{{"b", types.NewInt(1)}}, "ArgumentCountError", "f(): Argument #1 ($a) not passed"},
+	}
+	for _, tt := range errorTests {
+		_, _, err := vm.bindArguments("f", fn.Parameters, &CallParams{params: tt.positional, named: tt.named})
+		thrown, ok := err.(*ThrowableError)
+		if !ok || thrown.Object.ClassEntry.Name != tt.class || throwableProperty(thrown.Object, "message").ToString() != tt.message {
+			t.Errorf("Expected %s %q, got %v", tt.class, tt.message, err)
+		}
+	}
+
+	// Builtins only take positional arguments
+	_, _, err = vm.bindArguments("strlen", nil, &CallParams{named: []namedArg{{"string", types.NewString("x")}}})
+	if _, ok := err.(*ThrowableError); !ok {
+		t.Errorf("Expected an error for a named argument to a builtin, got %v", err)
+	}
+}
+
+func TestAddNamedArgument_Duplicate(t *testing.T) {
+	vm := New()
+	params := &CallParams{}
+	vm.addNamedArgument(params, "a", types.NewInt(1))
+	if err := vm.addNamedArgument(params, "a", types.NewInt(2)); err == nil {
+		t.Error("Expected an error for a repeated named argument")
+	}
+}
+
+func TestSendUnpack(t *testing.T) {
+	vm := New()
+	frame := NewFrame(mainScript(nil))
+
+	// f(1, ...[2, 'c' => 3])
+	arr := types.NewEmptyArray()
+	arr.Append(types.NewInt(2))
+	arr.Set(types.NewString("c"), types.NewInt(3))
+	frame.setLocal(10, types.NewArray(arr))
+	vm.constants = []interface{}{int64(1)}
+
+	for _, instr := range []Instruction{
+		{Opcode: OpSendVal, Op1: Operand{Type: OpConst, Value: 0}},
+		{Opcode: OpSendUnpack, Op1: Operand{Type: OpTmpVar, Value: 10}},
+	} {
+		if err := vm.dispatch(frame, instr); err != nil {
+			t.Fatalf("%s: unexpected error: %v", instr.Opcode, err)
+		}
+	}
+	params := frame.pendingParams
+	if len(params.params) != 2 || params.params[1].ToInt() != 2 {
+		t.Errorf("Expected positional arguments [1 2], got %v", params.params)
+	}
+	if len(params.named) != 1 || params.named[0].name != "c" || params.named[0].value.ToInt() != 3 {
+		t.Errorf("Expected named argument c: 3, got %v", params.named)
+	}
+
+	// Integer keys after string keys are rejected
+	more := types.NewEmptyArray()
+	more.Append(types.NewInt(4))
+	if err := vm.unpackArguments(params, types.NewArray(more)); err == nil {
+		t.Error("Expected an error for a positional argument after a named one")
+	}
+	if err := vm.unpackArguments(params, types.NewInt(1)); err == nil {
+		t.Error("Expected an error for unpacking a non-array")
+	}
+}
+
+func TestCallUserFuncArray_NamedArguments(t *testing.T) {
+	vm := New()
+
+	// f returns $b - $a * 10 - $c * 100
+	fn := paramsFunction()
+	fn.Instructions = Instructions{
+		{Opcode: OpMul, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 5}},
+		{Opcode: OpSub, Op1: Operand{Type: OpCV, Value: 1}, Op2: Operand{Type: OpTmpVar, Value: 5}, Result: Operand{Type: OpTmpVar, Value: 5}},
+		{Opcode: OpMul, Op1: Operand{Type: OpCV, Value: 2}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 6}},
+		{Opcode: OpSub, Op1: Operand{Type: OpTmpVar, Value: 5}, Op2: Operand{Type: OpTmpVar, Value: 6}, Result: Operand{Type: OpTmpVar, Value: 5}},
+		{Opcode: OpReturn, Op1: Operand{Type: OpTmpVar, Value: 5}},
+	}
+	vm.constants = []interface{}{int64(10), int64(100)}
+	vm.RegisterFunction("f", fn)
+
+	// call_user_func_array('f', ['c' => 3, 'a' => 1]) is f(1, 2, 3)
+	args := types.NewEmptyArray()
+	args.Set(types.NewString("c"), types.NewInt(3))
+	args.Set(types.NewString("a"), types.NewInt(1))
+	result, err := builtinCallUserFuncArray(vm, []*types.Value{types.NewString("f"), types.NewArray(args)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ToInt() != 2-10-300 {
+		t.Errorf("Expected f(1, 2, 3) = %d, got %v", 2-10-300, result)
+	}
+
+	// A builtin rejects names
+	_, err = builtinCallUserFuncArray(vm, []*types.Value{types.NewString("is_callable"), types.NewArray(args)})
+	if _, ok := err.(*ThrowableError); !ok {
+		t.Errorf("Expected an Error for named arguments to a builtin, got %v", err)
+	}
+}
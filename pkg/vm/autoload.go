@@ -0,0 +1,305 @@
+package vm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Class Autoloading
+// ============================================================================
+
+// Autoloader is a Go class loader. It is called with the fully qualified name
+// of a class that is not declared yet and should declare it, for example by
+// including a file. Returning an error aborts the class lookup.
+type Autoloader func(className string) error
+
+// RegisterAutoloader appends a Go autoloader to the autoload queue. It is
+// exposed to PHP as a Closure, so spl_autoload_functions() lists it alongside
+// the callbacks registered with spl_autoload_register().
+func (vm *VM) RegisterAutoloader(loader func(name string) error) {
+	vm.autoloaders = append(vm.autoloaders, nativeAutoloader(loader))
+}
+
+// AddPsr4Namespace maps a namespace prefix to a base directory for the
+// built-in PSR-4 loader: App\Models\User is loaded from <dir>/Models/User.php
+// when the prefix is App\. The loader is registered on first use.
+func (vm *VM) AddPsr4Namespace(prefix, dir string) {
+	prefix = strings.Trim(prefix, "\\")
+	if prefix != "" {
+		prefix += "\\"
+	}
+
+	if vm.psr4Prefixes == nil {
+		vm.psr4Prefixes = make(map[string][]string)
+		vm.RegisterAutoloader(vm.loadPsr4Class)
+	}
+	vm.psr4Prefixes[prefix] = append(vm.psr4Prefixes[prefix], dir)
+}
+
+// AddClassMap maps class names to the files declaring them, as Composer's
+// class map does. The class map loader is registered on first use; a class
+// missing from the map, or whose file does not exist, is left to the
+// loaders after it.
+func (vm *VM) AddClassMap(classes map[string]string) {
+	if vm.classMap == nil {
+		vm.classMap = make(map[string]string, len(classes))
+		vm.RegisterAutoloader(vm.loadMappedClass)
+	}
+	for name, path := range classes {
+		vm.classMap[strings.ToLower(strings.TrimPrefix(name, "\\"))] = path
+	}
+}
+
+// nativeAutoloader wraps a Go autoloader in a Closure callable
+func nativeAutoloader(loader Autoloader) *types.Value {
+	return newClosureObject(&Closure{
+		Builtin: func(vm *VM, args []*types.Value) (*types.Value, error) {
+			if len(args) == 0 {
+				return types.NewNull(), nil
+			}
+			return types.NewNull(), loader(args[0].ToString())
+		},
+		CapturedVars: make(map[string]*types.Value),
+		Static:       true,
+	})
+}
+
+// loadClass looks up a class, running the autoloaders if it is not declared yet
+func (vm *VM) loadClass(name string) (*CompiledClass, bool, error) {
+	if class, ok := vm.lookupClass(name); ok {
+		return class, true, nil
+	}
+	if err := vm.autoload(name); err != nil {
+		return nil, false, err
+	}
+	class, ok := vm.lookupClass(name)
+	return class, ok, nil
+}
+
+// autoload calls the registered autoloaders in order until the class exists
+func (vm *VM) autoload(name string) error {
+	name = strings.TrimPrefix(name, "\\")
+	if name == "" || len(vm.autoloaders) == 0 {
+		return nil
+	}
+
+	// A class being autoloaded is not autoloaded again while its loader runs
+	key := strings.ToLower(name)
+	if vm.autoloading[key] {
+		return nil
+	}
+	vm.autoloading[key] = true
+	defer delete(vm.autoloading, key)
+
+	// Loaders may register or unregister loaders, so iterate over a snapshot
+	loaders := append([]*types.Value(nil), vm.autoloaders...)
+	for _, loader := range loaders {
+		if _, err := vm.CallCallable(loader, []*types.Value{types.NewString(name)}); err != nil {
+			return err
+		}
+		if _, ok := vm.lookupClass(name); ok {
+			return nil
+		}
+	}
+	return nil
+}
+
+// loadPsr4Class is the built-in PSR-4 autoloader
+func (vm *VM) loadPsr4Class(name string) error {
+	path, ok := vm.findPsr4File(name)
+	if !ok {
+		return nil
+	}
+	return vm.requireFile(path)
+}
+
+// loadMappedClass is the class map autoloader
+func (vm *VM) loadMappedClass(name string) error {
+	path, ok := vm.classMap[strings.ToLower(name)]
+	if !ok {
+		return nil
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return nil
+	}
+	return vm.requireFile(realPath(path))
+}
+
+// findPsr4File maps a class name to a file using the longest matching prefix
+func (vm *VM) findPsr4File(name string) (string, bool) {
+	bestPrefix := ""
+	found := false
+	for prefix := range vm.psr4Prefixes {
+		if strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix)) && (!found || len(prefix) > len(bestPrefix)) {
+			bestPrefix = prefix
+			found = true
+		}
+	}
+	if !found {
+		return "", false
+	}
+
+	relative := strings.ReplaceAll(name[len(bestPrefix):], "\\", string(filepath.Separator)) + ".php"
+	for _, dir := range vm.psr4Prefixes[bestPrefix] {
+		path := filepath.Join(dir, relative)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return realPath(path), true
+		}
+	}
+	return "", false
+}
+
+// requireFile executes a file once in its own variable scope (require_once
+// semantics, as used by autoloaders)
+func (vm *VM) requireFile(path string) error {
+	if vm.includedFiles[path] {
+		return nil
+	}
+
+	fn, err := vm.compileFile(path)
+	if err != nil {
+		return err
+	}
+	vm.markIncluded(path)
+
+	_, err = vm.executeIncluded(NewFrame(&CompiledFunction{Name: "autoload"}), fn, path)
+	return err
+}
+
+// sameCallable reports whether two callables refer to the same function
+func sameCallable(a, b *types.Value) bool {
+	a, b = a.Deref(), b.Deref()
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Type() {
+	case types.TypeString:
+		return strings.EqualFold(strings.TrimPrefix(a.ToString(), "\\"), strings.TrimPrefix(b.ToString(), "\\"))
+	case types.TypeObject:
+		return a.ToObject() == b.ToObject()
+	case types.TypeArray:
+		if a.ToArray().Len() != 2 || b.ToArray().Len() != 2 {
+			return false
+		}
+		for i := int64(0); i < 2; i++ {
+			x, _ := a.ToArray().Get(types.NewInt(i))
+			y, _ := b.ToArray().Get(types.NewInt(i))
+			if x == nil || y == nil || !sameCallable(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// ============================================================================
+// Autoload Builtins
+// ============================================================================
+
+// registerAutoloadBuiltins registers the spl_autoload_* functions
+func (vm *VM) registerAutoloadBuiltins() {
+	vm.RegisterBuiltin("spl_autoload_register", builtinSplAutoloadRegister)
+	vm.RegisterBuiltin("spl_autoload_unregister", builtinSplAutoloadUnregister)
+	vm.RegisterBuiltin("spl_autoload_functions", builtinSplAutoloadFunctions)
+	vm.RegisterBuiltin("spl_autoload_call", builtinSplAutoloadCall)
+	vm.RegisterBuiltin("spl_autoload_extensions", builtinSplAutoloadExtensions)
+	vm.RegisterBuiltin("spl_autoload", builtinSplAutoload)
+}
+
+// spl_autoload_register(?callable $callback = null, bool $throw = true, bool $prepend = false): bool
+func builtinSplAutoloadRegister(vm *VM, args []*types.Value) (*types.Value, error) {
+	var callback *types.Value
+	if len(args) > 0 && !args[0].Deref().IsNull() {
+		callback = args[0]
+		if _, err := vm.resolveCallable(callback); err != nil {
+			return nil, vm.ThrowError("TypeError", "spl_autoload_register(): Argument #1 ($callback) must be a valid callback or null, %v", err)
+		}
+	} else {
+		// No callback registers the default spl_autoload() implementation
+		callback = types.NewString("spl_autoload")
+	}
+
+	for _, registered := range vm.autoloaders {
+		if sameCallable(registered, callback) {
+			return types.NewBool(true), nil
+		}
+	}
+
+	if len(args) > 2 && args[2].ToBool() {
+		vm.autoloaders = append([]*types.Value{callback}, vm.autoloaders...)
+	} else {
+		vm.autoloaders = append(vm.autoloaders, callback)
+	}
+	return types.NewBool(true), nil
+}
+
+// spl_autoload_unregister(callable $callback): bool
+func builtinSplAutoloadUnregister(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("spl_autoload_unregister() expects exactly 1 argument, %d given", len(args))
+	}
+
+	for i, registered := range vm.autoloaders {
+		if sameCallable(registered, args[0]) {
+			vm.autoloaders = append(vm.autoloaders[:i:i], vm.autoloaders[i+1:]...)
+			return types.NewBool(true), nil
+		}
+	}
+	return types.NewBool(false), nil
+}
+
+// spl_autoload_functions(): array
+func builtinSplAutoloadFunctions(vm *VM, args []*types.Value) (*types.Value, error) {
+	functions := types.NewEmptyArray()
+	for _, loader := range vm.autoloaders {
+		functions.Append(loader)
+	}
+	return types.NewArray(functions), nil
+}
+
+// spl_autoload_call(string $class): void
+func builtinSplAutoloadCall(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("spl_autoload_call() expects exactly 1 argument, %d given", len(args))
+	}
+	return types.NewNull(), vm.autoload(args[0].ToString())
+}
+
+// spl_autoload_extensions(?string $file_extensions = null): string
+func builtinSplAutoloadExtensions(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) > 0 && !args[0].IsNull() {
+		vm.autoloadExtensions = strings.Split(args[0].ToString(), ",")
+	}
+	return types.NewString(strings.Join(vm.autoloadExtensions, ",")), nil
+}
+
+// spl_autoload(string $class, ?string $file_extensions = null): void
+// Looks for the lowercased class name with each extension in the include_path
+func builtinSplAutoload(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("spl_autoload() expects at least 1 argument, 0 given")
+	}
+
+	extensions := vm.autoloadExtensions
+	if len(args) > 1 && !args[1].IsNull() {
+		extensions = strings.Split(args[1].ToString(), ",")
+	}
+
+	base := strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(args[0].ToString(), "\\")), "\\", string(filepath.Separator))
+	for _, ext := range extensions {
+		for _, dir := range vm.includePath {
+			path := filepath.Join(dir, base+ext)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return types.NewNull(), vm.requireFile(realPath(path))
+			}
+		}
+	}
+	return types.NewNull(), nil
+}
@@ -0,0 +1,177 @@
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// newInstr emits: T1 = new constants[class]
+func newInstr(class uint32) Instruction {
+	return Instruction{Opcode: OpNew, Op1: Operand{Type: OpConst, Value: class}, Result: Operand{Type: OpTmpVar, Value: 1}}
+}
+
+func TestAutoload_GoLoaderDeclaresMissingClass(t *testing.T) {
+	vm := New()
+	var requested []string
+	vm.RegisterAutoloader(func(name string) error {
+		requested = append(requested, name)
+		if name == "App\\Lazy" {
+			vm.RegisterClass(types.NewClassEntry("App\\Lazy"))
+		}
+		return nil
+	})
+	vm.constants = []interface{}{"\\App\\Lazy", "App\\Missing"}
+
+	if err := runMain(vm, mainScript(Instructions{newInstr(0), newInstr(0)})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requested) != 1 || requested[0] != "App\\Lazy" {
+		t.Errorf("Expected a single autoload of App\\Lazy, got %v", requested)
+	}
+
+	err := runMain(vm, mainScript(Instructions{newInstr(1)}))
+	if err == nil || err.Error() != "Class 'App\\Missing' not found" {
+		t.Errorf("Expected class not found after autoloaders ran, got %v", err)
+	}
+	if len(requested) != 2 {
+		t.Errorf("Expected autoloader to be asked for App\\Missing, got %v", requested)
+	}
+}
+
+func TestAutoload_SplAutoloadRegister(t *testing.T) {
+	vm := New()
+	var order []string
+	loader := func(name string) BuiltinFunction {
+		return func(vm *VM, args []*types.Value) (*types.Value, error) {
+			order = append(order, name+":"+args[0].ToString())
+			return types.NewNull(), nil
+		}
+	}
+	vm.RegisterBuiltin("first_loader", loader("first"))
+	vm.RegisterBuiltin("second_loader", loader("second"))
+
+	call := func(fn string, args ...*types.Value) *types.Value {
+		t.Helper()
+		result, err := vm.CallCallable(types.NewString(fn), args)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", fn, err)
+		}
+		return result
+	}
+
+	call("spl_autoload_register", types.NewString("first_loader"))
+	call("spl_autoload_register", types.NewString("First_Loader")) // Already registered
+	call("spl_autoload_register", types.NewString("second_loader"), types.NewBool(true), types.NewBool(true))
+
+	if functions := call("spl_autoload_functions").ToArray(); functions.Len() != 2 {
+		t.Fatalf("Expected 2 autoloaders, got %d", functions.Len())
+	}
+
+	call("spl_autoload_call", types.NewString("Foo"))
+	if len(order) != 2 || order[0] != "second:Foo" || order[1] != "first:Foo" {
+		t.Errorf("Expected prepended loader to run first, got %v", order)
+	}
+
+	if !call("spl_autoload_unregister", types.NewString("second_loader")).ToBool() {
+		t.Error("Expected second_loader to be unregistered")
+	}
+	if call("spl_autoload_unregister", types.NewString("second_loader")).ToBool() {
+		t.Error("Unregistering twice should return false")
+	}
+
+	if _, err := vm.CallCallable(types.NewString("spl_autoload_register"), []*types.Value{types.NewString("nope")}); err == nil {
+		t.Error("Expected TypeError for an invalid callback")
+	}
+}
+
+func TestAutoload_ExceptionPropagatesAndNoRecursion(t *testing.T) {
+	vm := New()
+	calls := 0
+	vm.RegisterAutoloader(func(name string) error {
+		calls++
+		// Looking up the class being loaded must not re-enter the loader
+		if _, ok, err := vm.loadClass(name); ok || err != nil {
+			t.Errorf("Nested lookup should fail quietly, got %v, %v", ok, err)
+		}
+		return vm.ThrowError("RuntimeException", "cannot load %s", name)
+	})
+	vm.constants = []interface{}{"Broken"}
+
+	err := runMain(vm, mainScript(Instructions{newInstr(0)}))
+	throwable, ok := err.(*ThrowableError)
+	if !ok || throwable.Object.ClassEntry.Name != "RuntimeException" {
+		t.Fatalf("Expected RuntimeException from the autoloader, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the autoloader to run once, ran %d times", calls)
+	}
+}
+
+func TestAutoload_Psr4(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "Models"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := writeScript(t, filepath.Join(dir, "Models"), "User.php", "user")
+
+	compiler := &fakeCompiler{scripts: map[string]*Script{"user": {
+		Instructions: Instructions{{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 0}}},
+		Constants:    []interface{}{"loaded"},
+	}}}
+
+	vm := New()
+	vm.SetScriptCompiler(compiler.compile)
+	vm.AddPsr4Namespace("App\\", dir)
+	vm.AddPsr4Namespace("App\\Models\\Deep", t.TempDir())
+
+	if found, ok := vm.findPsr4File("App\\Models\\User"); !ok || found != path {
+		t.Errorf("Expected App\\Models\\User to map to %s, got %q", path, found)
+	}
+	if _, ok := vm.findPsr4File("Other\\User"); ok {
+		t.Error("Classes outside the registered prefixes should not be mapped")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := vm.loadClass("App\\Models\\User"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if vm.GetOutput() != "loaded" {
+		t.Errorf("Expected class file to be required once, got output %q", vm.GetOutput())
+	}
+	if files := vm.IncludedFiles(); len(files) != 1 || files[0] != path {
+		t.Errorf("Expected included files [%s], got %v", path, files)
+	}
+}
+
+func TestAutoload_ClassMap(t *testing.T) {
+	dir := t.TempDir()
+	path := writeScript(t, dir, "helpers.php", "helpers")
+
+	compiler := &fakeCompiler{scripts: map[string]*Script{"helpers": {
+		Instructions: Instructions{{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 0}}},
+		Constants:    []interface{}{"loaded"},
+	}}}
+
+	vm := New()
+	vm.SetScriptCompiler(compiler.compile)
+	vm.AddClassMap(map[string]string{
+		"App\\Support\\Helpers": path,
+		"App\\Gone":             filepath.Join(dir, "gone.php"),
+	})
+
+	for _, name := range []string{"app\\support\\HELPERS", "App\\Support\\Helpers", "App\\Gone", "App\\Unmapped"} {
+		if _, _, err := vm.loadClass(name); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+	}
+	if vm.GetOutput() != "loaded" {
+		t.Errorf("Expected the mapped file to be required once, got output %q", vm.GetOutput())
+	}
+	if files := vm.IncludedFiles(); len(files) != 1 || files[0] != path {
+		t.Errorf("Expected included files [%s], got %v", path, files)
+	}
+}
@@ -0,0 +1,230 @@
+package vm
+
+import (
+	"fmt"
+
+	stdarray "github.com/krizos/php-go/pkg/stdlib/array"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Array Builtins
+// ============================================================================
+
+// registerArrayBuiltins registers the array functions that take callbacks
+// and the sort functions, which sort their argument in place. The VM
+// itself is passed as the stdlib.Caller, so callbacks may be any PHP
+// callable: function names, closures, [$obj, 'method'], etc.
+func (vm *VM) registerArrayBuiltins() {
+	vm.RegisterBuiltin("array_map", builtinArrayMap)
+	vm.RegisterBuiltin("array_filter", builtinArrayFilter)
+	vm.RegisterBuiltin("array_reduce", builtinArrayReduce)
+	vm.RegisterBuiltin("array_walk", builtinArrayWalk)
+	vm.RegisterBuiltin("usort", builtinUsort)
+	vm.RegisterBuiltin("uasort", builtinUasort)
+	vm.RegisterBuiltin("uksort", builtinUksort)
+	vm.RegisterBuiltin("array_udiff", builtinArrayUdiff)
+
+	for name, sort := range sortFunctions {
+		vm.RegisterBuiltin(name, sortBuiltin(name, sort))
+	}
+	vm.RegisterBuiltin("natsort", builtinNatsort)
+	vm.RegisterBuiltin("natcasesort", builtinNatcasesort)
+	vm.RegisterBuiltin("array_multisort", builtinArrayMultisort)
+
+	for name, fn := range arrayFunctions {
+		vm.RegisterBuiltin(name, fn.builtin(name))
+	}
+}
+
+// arrayFunction adapts a pkg/stdlib/array function to a builtin, like
+// mathFunction; the VM is passed for functions using the calling scope
+type arrayFunction struct {
+	required int
+	call     func(vm *VM, args []*types.Value) (*types.Value, error)
+}
+
+// pure adapts an array function that cannot fail
+func pure(fn func(a []*types.Value) *types.Value) func(vm *VM, a []*types.Value) (*types.Value, error) {
+	return func(vm *VM, a []*types.Value) (*types.Value, error) {
+		return fn(a), nil
+	}
+}
+
+// arrayFunctions maps the array functions without callbacks to their
+// pkg/stdlib/array implementations
+var arrayFunctions = map[string]arrayFunction{
+	"array_key_exists": {2, func(vm *VM, a []*types.Value) (*types.Value, error) {
+		return stdarray.ArrayKeyExists(a[0], a[1])
+	}},
+	"key_exists": {2, func(vm *VM, a []*types.Value) (*types.Value, error) {
+		return stdarray.ArrayKeyExists(a[0], a[1])
+	}},
+	"array_column":        {2, pure(func(a []*types.Value) *types.Value { return stdarray.ArrayColumn(a[0], a[1], a[2:]...) })},
+	"array_fill_keys":     {2, pure(func(a []*types.Value) *types.Value { return stdarray.ArrayFillKeys(a[0], a[1]) })},
+	"array_pad":           {3, pure(func(a []*types.Value) *types.Value { return stdarray.ArrayPad(a[0], a[1], a[2]) })},
+	"array_diff_key":      {1, pure(func(a []*types.Value) *types.Value { return stdarray.ArrayDiffKey(a...) })},
+	"array_intersect_key": {1, pure(func(a []*types.Value) *types.Value { return stdarray.ArrayIntersectKey(a...) })},
+	"array_replace":       {1, pure(func(a []*types.Value) *types.Value { return stdarray.ArrayReplace(a...) })},
+	"array_sum":           {1, pure(func(a []*types.Value) *types.Value { return stdarray.ArraySum(a[0]) })},
+	"array_product":       {1, pure(func(a []*types.Value) *types.Value { return stdarray.ArrayProduct(a[0]) })},
+	"shuffle":             {1, pure(func(a []*types.Value) *types.Value { return stdarray.Shuffle(a[0]) })},
+	"range": {2, func(vm *VM, a []*types.Value) (*types.Value, error) {
+		return stdarray.Range(a[0], a[1], a[2:]...)
+	}},
+	"array_rand": {1, func(vm *VM, a []*types.Value) (*types.Value, error) {
+		return stdarray.ArrayRand(a[0], a[1:]...)
+	}},
+	"compact": {1, func(vm *VM, a []*types.Value) (*types.Value, error) {
+		return stdarray.Compact(frameScope{vm, vm.currentFrame()}, a...), nil
+	}},
+	"extract": {1, func(vm *VM, a []*types.Value) (*types.Value, error) {
+		return stdarray.Extract(frameScope{vm, vm.currentFrame()}, a[0], a[1:]...)
+	}},
+}
+
+// builtin wraps the function with an argument count check. Errors of the
+// array package are thrown as the exception class they name.
+func (fn arrayFunction) builtin(name string) BuiltinFunction {
+	return func(vm *VM, args []*types.Value) (*types.Value, error) {
+		if len(args) < fn.required {
+			return nil, fmt.Errorf("%s() expects at least %d argument(s), %d given", name, fn.required, len(args))
+		}
+		result, err := fn.call(vm, derefArgs(args))
+		if e, ok := err.(*stdarray.Error); ok {
+			return nil, vm.ThrowError(e.Class, "%s", e.Message)
+		}
+		return result, err
+	}
+}
+
+// array_map(?callable $callback, array $array, array ...$arrays): array
+func builtinArrayMap(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("array_map() expects at least 2 arguments, %d given", len(args))
+	}
+	return stdarray.ArrayMap(vm, args[0], derefArgs(args[1:])...)
+}
+
+// array_filter(array $array, ?callable $callback = null, int $mode = 0): array
+func builtinArrayFilter(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("array_filter() expects at least 1 argument, 0 given")
+	}
+	return stdarray.ArrayFilter(vm, args[0].Deref(), args[1:]...)
+}
+
+// array_reduce(array $array, callable $callback, mixed $initial = null): mixed
+func builtinArrayReduce(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("array_reduce() expects at least 2 arguments, %d given", len(args))
+	}
+	return stdarray.ArrayReduce(vm, args[0].Deref(), args[1], args[2:]...)
+}
+
+// array_walk(array &$array, callable $callback, mixed $arg = null): true
+func builtinArrayWalk(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("array_walk() expects at least 2 arguments, %d given", len(args))
+	}
+	return stdarray.ArrayWalk(vm, args[0].Deref(), args[1], args[2:]...)
+}
+
+// usort(array &$array, callable $callback): true
+func builtinUsort(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("usort() expects exactly 2 arguments, %d given", len(args))
+	}
+	return stdarray.Usort(vm, args[0].Deref(), args[1])
+}
+
+// uasort(array &$array, callable $callback): true
+func builtinUasort(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("uasort() expects exactly 2 arguments, %d given", len(args))
+	}
+	return stdarray.Uasort(vm, args[0].Deref(), args[1])
+}
+
+// uksort(array &$array, callable $callback): true
+func builtinUksort(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("uksort() expects exactly 2 arguments, %d given", len(args))
+	}
+	return stdarray.Uksort(vm, args[0].Deref(), args[1])
+}
+
+// sortFunctions maps the sort functions taking an array and optional sort
+// flags to their pkg/stdlib/array implementations
+var sortFunctions = map[string]func(arr *types.Value, flags ...*types.Value) *types.Value{
+	"sort":   stdarray.Sort,
+	"rsort":  stdarray.Rsort,
+	"asort":  stdarray.Asort,
+	"arsort": stdarray.Arsort,
+	"ksort":  stdarray.Ksort,
+	"krsort": stdarray.Krsort,
+}
+
+// sortBuiltin adapts a sort function: sort(array &$array, int $flags = SORT_REGULAR): true
+func sortBuiltin(name string, sort func(arr *types.Value, flags ...*types.Value) *types.Value) BuiltinFunction {
+	return func(vm *VM, args []*types.Value) (*types.Value, error) {
+		if len(args) < 1 || len(args) > 2 {
+			return nil, fmt.Errorf("%s() expects 1 or 2 arguments, %d given", name, len(args))
+		}
+		return sort(args[0].Deref(), derefArgs(args[1:])...), nil
+	}
+}
+
+// natsort(array &$array): true
+func builtinNatsort(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("natsort() expects exactly 1 argument, %d given", len(args))
+	}
+	return stdarray.Natsort(args[0].Deref()), nil
+}
+
+// natcasesort(array &$array): true
+func builtinNatcasesort(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("natcasesort() expects exactly 1 argument, %d given", len(args))
+	}
+	return stdarray.Natcasesort(args[0].Deref()), nil
+}
+
+// array_multisort(array &$array1, mixed $array1_sort_order = SORT_ASC, mixed $array1_sort_flags = SORT_REGULAR, mixed ...$rest): bool
+func builtinArrayMultisort(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("array_multisort() expects at least 1 argument, 0 given")
+	}
+	result, err := stdarray.ArrayMultisort(args...)
+	if e, ok := err.(*stdarray.Error); ok {
+		return nil, vm.ThrowError(e.Class, "%s", e.Message)
+	}
+	return result, err
+}
+
+// array_udiff(array $array, array ...$arrays, callable $value_compare_func): array
+func builtinArrayUdiff(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("array_udiff() expects at least 3 arguments, %d given", len(args))
+	}
+	arrays := derefArgs(args[:len(args)-1])
+	return stdarray.ArrayUdiff(vm, append(arrays, args[len(args)-1])...)
+}
+
+// derefArgs dereferences every argument
+func derefArgs(args []*types.Value) []*types.Value {
+	result := make([]*types.Value, len(args))
+	for i, arg := range args {
+		result[i] = arg.Deref()
+	}
+	return result
+}
+
+// assignRefArg writes a value to a by-reference argument ($matches, $count)
+// when the caller passed one
+func assignRefArg(args []*types.Value, index int, value *types.Value) {
+	if index < len(args) && args[index] != nil {
+		args[index].Assign(value)
+	}
+}
@@ -0,0 +1,134 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func intArray(values ...int64) *types.Value {
+	arr := types.NewEmptyArray()
+	for _, v := range values {
+		arr.Append(types.NewInt(v))
+	}
+	return types.NewArray(arr)
+}
+
+func TestBuiltin_ArrayMapUserFunction(t *testing.T) {
+	vm := newCallableTestVM()
+
+	result, err := builtinArrayMap(vm, []*types.Value{types.NewString("double"), intArray(1, 2, 3)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []int64{2, 4, 6}
+	for i, want := range expected {
+		val, _ := result.ToArray().Get(types.NewInt(int64(i)))
+		if val.ToInt() != want {
+			t.Errorf("Index %d: expected %d, got %v", i, want, val)
+		}
+	}
+}
+
+func TestBuiltin_ArrayMapClosureAndMethod(t *testing.T) {
+	vm := newCallableTestVM()
+	obj := types.NewObject(types.NewObjectFromClass(vm.classes["Math"]))
+
+	callbacks := []*types.Value{
+		newClosureObject(&Closure{Function: vm.functions["double"]}),
+		callableArray(obj, "twice"),
+		types.NewString("Math::staticTwice"),
+	}
+
+	for _, callback := range callbacks {
+		result, err := builtinArrayMap(vm, []*types.Value{callback, intArray(5)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		val, _ := result.ToArray().Get(types.NewInt(0))
+		if val.ToInt() != 10 {
+			t.Errorf("Expected 10, got %v", val)
+		}
+	}
+}
+
+func TestBuiltin_UsortUserComparator(t *testing.T) {
+	vm := New()
+
+	// function cmp($a, $b) { return $a - $b; }
+	vm.RegisterFunction("cmp", &CompiledFunction{
+		Name: "cmp",
+		Instructions: Instructions{
+			{Opcode: OpSub, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpCV, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 0}},
+			{Opcode: OpReturn, Op1: Operand{Type: OpTmpVar, Value: 0}},
+		},
+		NumLocals: 10,
+		NumParams: 2,
+	})
+
+	arr := intArray(3, 1, 2)
+	if _, err := vm.CallCallable(types.NewString("usort"), []*types.Value{arr, types.NewString("cmp")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, want := range []int64{1, 2, 3} {
+		val, _ := arr.ToArray().Get(types.NewInt(int64(i)))
+		if val.ToInt() != want {
+			t.Errorf("Index %d: expected %d, got %v", i, want, val)
+		}
+	}
+}
+
+func TestBuiltin_ArrayFilterUndefinedCallback(t *testing.T) {
+	vm := New()
+
+	_, err := builtinArrayFilter(vm, []*types.Value{intArray(1), types.NewString("nope")})
+	if err == nil || err.Error() != "Call to undefined function nope()" {
+		t.Errorf("Expected undefined function error, got %v", err)
+	}
+}
+
+func TestBuiltin_SortFlagsAndMultisort(t *testing.T) {
+	vm := New()
+
+	files := types.NewEmptyArray()
+	files.Push(types.NewString("img12.png"), types.NewString("IMG10.png"), types.NewString("img2.png"))
+	if _, err := vm.builtins["sort"](vm, []*types.Value{types.NewReference(types.NewArray(files)), types.NewInt(6 | 8)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first, _ := files.Get(types.NewInt(0)); first.ToString() != "img2.png" {
+		t.Errorf("Expected SORT_NATURAL | SORT_FLAG_CASE to sort img2.png first, got %v", first)
+	}
+
+	_, err := builtinArrayMultisort(vm, []*types.Value{intArray(1, 2), intArray(1)})
+	expectThrown(t, err, "ValueError", "Array sizes are inconsistent")
+}
+
+func TestBuiltin_ExtractAndCompactUseCallingFrame(t *testing.T) {
+	vm := New()
+	frame := NewFrame(&CompiledFunction{Name: "f", NumLocals: 4, Variables: []string{"x"}})
+	vm.pushFrame(frame)
+
+	vars := types.NewEmptyArray()
+	vars.Set(types.NewString("x"), types.NewInt(1))
+	vars.Set(types.NewString("y"), types.NewInt(2))
+	count, err := vm.builtins["extract"](vm, []*types.Value{types.NewArray(vars)})
+	if err != nil || count.ToInt() != 2 {
+		t.Fatalf("Expected extract() to import 2 variables, got %v (%v)", count, err)
+	}
+	if frame.getLocal(0).Deref().ToInt() != 1 {
+		t.Errorf("Expected extract() to assign the compiled variable $x, got %v", frame.getLocal(0))
+	}
+
+	result, err := vm.builtins["compact"](vm, []*types.Value{types.NewString("x"), types.NewString("y")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if y, _ := result.ToArray().Get(types.NewString("y")); y.ToInt() != 2 {
+		t.Errorf("Expected compact() to find the variable $y created by name, got %v", y)
+	}
+
+	_, err = vm.builtins["range"](vm, []*types.Value{types.NewInt(1), types.NewInt(2), types.NewInt(0)})
+	expectThrown(t, err, "ValueError", "range(): Argument #3 ($step) cannot be 0")
+}
@@ -0,0 +1,71 @@
+package vm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/stdlib/bcmath"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// BCMath Builtins
+// ============================================================================
+
+// bcmathFunction is a BCMath builtin with its argument counts
+type bcmathFunction struct {
+	required, max int
+	call          func(c *bcmath.Calculator, args []*types.Value) (*types.Value, error)
+}
+
+// bcmathFunctions maps the BCMath functions to their pkg/stdlib/bcmath
+// implementations
+var bcmathFunctions = map[string]bcmathFunction{
+	"bcadd": {2, 3, func(c *bcmath.Calculator, a []*types.Value) (*types.Value, error) { return c.Add(a[0], a[1], a[2:]...) }},
+	"bcsub": {2, 3, func(c *bcmath.Calculator, a []*types.Value) (*types.Value, error) { return c.Sub(a[0], a[1], a[2:]...) }},
+	"bcmul": {2, 3, func(c *bcmath.Calculator, a []*types.Value) (*types.Value, error) { return c.Mul(a[0], a[1], a[2:]...) }},
+	"bcdiv": {2, 3, func(c *bcmath.Calculator, a []*types.Value) (*types.Value, error) { return c.Div(a[0], a[1], a[2:]...) }},
+	"bcmod": {2, 3, func(c *bcmath.Calculator, a []*types.Value) (*types.Value, error) { return c.Mod(a[0], a[1], a[2:]...) }},
+	"bcpow": {2, 3, func(c *bcmath.Calculator, a []*types.Value) (*types.Value, error) { return c.Pow(a[0], a[1], a[2:]...) }},
+	"bcsqrt": {1, 2, func(c *bcmath.Calculator, a []*types.Value) (*types.Value, error) {
+		return c.Sqrt(a[0], a[1:]...)
+	}},
+	"bccomp": {2, 3, func(c *bcmath.Calculator, a []*types.Value) (*types.Value, error) {
+		return c.Comp(a[0], a[1], a[2:]...)
+	}},
+	"bcscale": {0, 1, func(c *bcmath.Calculator, a []*types.Value) (*types.Value, error) {
+		return c.SetScale(a...)
+	}},
+}
+
+// registerBcmathBuiltins registers the BCMath functions
+func (vm *VM) registerBcmathBuiltins() {
+	for name, fn := range bcmathFunctions {
+		vm.RegisterBuiltin(name, fn.builtin(name))
+	}
+}
+
+// builtin wraps the function with an argument count check. The function
+// runs with the default scale of the bcmath.scale directive, which
+// bcscale() changes.
+func (fn bcmathFunction) builtin(name string) BuiltinFunction {
+	return func(vm *VM, args []*types.Value) (*types.Value, error) {
+		if len(args) < fn.required || len(args) > fn.max {
+			return nil, fmt.Errorf("%s() expects %d to %d arguments, %d given", name, fn.required, fn.max, len(args))
+		}
+		scale, _ := vm.config.Get("bcmath.scale")
+		c := &bcmath.Calculator{}
+		c.Scale, _ = strconv.ParseInt(strings.TrimSpace(scale), 10, 64)
+
+		result, err := fn.call(c, derefArgs(args))
+		if e, ok := err.(*bcmath.Error); ok {
+			return nil, vm.ThrowError(e.Class, "%s", e.Message)
+		}
+		if err == nil && name == "bcscale" {
+			vm.config.Set("bcmath.scale", strconv.FormatInt(c.Scale, 10), runtime.INI_USER)
+		}
+		return result, err
+	}
+}
@@ -0,0 +1,32 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestBuiltin_BCMath(t *testing.T) {
+	vm := New()
+	call := func(name string, args ...*types.Value) (*types.Value, error) {
+		return vm.builtins[name](vm, args)
+	}
+
+	if result, err := call("bcadd", types.NewString("0.1"), types.NewString("0.2")); err != nil || result.ToString() != "0" {
+		t.Errorf("bcadd(\"0.1\", \"0.2\") = %v (%v)", result, err)
+	}
+
+	// bcscale() sets the bcmath.scale directive
+	if old, _ := call("bcscale", types.NewInt(2)); old.ToInt() != 0 {
+		t.Errorf("bcscale(2) = %v", old)
+	}
+	if scale, _ := vm.Config().Get("bcmath.scale"); scale != "2" {
+		t.Errorf("bcmath.scale = %q", scale)
+	}
+	if result, _ := call("bcadd", types.NewString("0.1"), types.NewString("0.2")); result.ToString() != "0.30" {
+		t.Errorf("bcadd(\"0.1\", \"0.2\") = %v with scale 2", result)
+	}
+
+	_, err := call("bcdiv", types.NewString("1"), types.NewString("0"))
+	expectThrown(t, err, "DivisionByZeroError", "Division by zero")
+}
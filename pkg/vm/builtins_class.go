@@ -0,0 +1,124 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/stdlib/classobj"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Class Builtins
+// ============================================================================
+
+// registerClassBuiltins registers the class and object functions
+func (vm *VM) registerClassBuiltins() {
+	vm.RegisterBuiltin("get_called_class", builtinGetCalledClass)
+
+	for name, fn := range classFunctions {
+		vm.RegisterBuiltin(name, fn.builtin(name))
+	}
+}
+
+// get_called_class(): string
+func builtinGetCalledClass(vm *VM, args []*types.Value) (*types.Value, error) {
+	return vm.calledClassName(vm.currentFrame())
+}
+
+// calledClassName returns the name of the class a static call was made on,
+// as get_called_class() reports it
+func (vm *VM) calledClassName(frame *Frame) (*types.Value, error) {
+	if frame == nil || (frame.calledClass == nil && frame.currentClass == nil) {
+		return nil, vm.ThrowError("Error", "get_called_class() must be called from within a class")
+	}
+	if frame.calledClass != nil {
+		return types.NewString(frame.calledClass.Name), nil
+	}
+	return types.NewString(frame.currentClass.Name), nil
+}
+
+// frameClasses implements classobj.Classes for the functions called from
+// a frame, whose class scope decides the members they see
+type frameClasses struct {
+	vm    *VM
+	frame *Frame
+}
+
+func (c frameClasses) LookupClass(name string, autoload bool) (*types.ClassEntry, bool, error) {
+	if autoload {
+		return c.vm.loadClass(name)
+	}
+	class, ok := c.vm.lookupClass(name)
+	return class, ok, nil
+}
+
+func (c frameClasses) Scope() *types.ClassEntry {
+	if c.frame == nil {
+		return nil
+	}
+	return c.frame.currentClass
+}
+
+// classFunction adapts a pkg/stdlib/classobj function to a builtin, like
+// arrayFunction
+type classFunction struct {
+	required int
+	call     func(c classobj.Classes, a []*types.Value) (*types.Value, error)
+}
+
+// classFunctions maps the class and object functions to their
+// pkg/stdlib/classobj implementations
+var classFunctions = map[string]classFunction{
+	"class_exists": {1, func(c classobj.Classes, a []*types.Value) (*types.Value, error) {
+		return classobj.ClassExists(c, a[0], a[1:]...)
+	}},
+	"interface_exists": {1, func(c classobj.Classes, a []*types.Value) (*types.Value, error) {
+		return classobj.InterfaceExists(c, a[0], a[1:]...)
+	}},
+	"trait_exists": {1, func(c classobj.Classes, a []*types.Value) (*types.Value, error) {
+		return classobj.TraitExists(c, a[0], a[1:]...)
+	}},
+	"enum_exists": {1, func(c classobj.Classes, a []*types.Value) (*types.Value, error) {
+		return classobj.EnumExists(c, a[0], a[1:]...)
+	}},
+	"method_exists": {2, func(c classobj.Classes, a []*types.Value) (*types.Value, error) {
+		return classobj.MethodExists(c, a[0], a[1])
+	}},
+	"property_exists": {2, func(c classobj.Classes, a []*types.Value) (*types.Value, error) {
+		return classobj.PropertyExists(c, a[0], a[1])
+	}},
+	"get_class": {0, func(c classobj.Classes, a []*types.Value) (*types.Value, error) {
+		return classobj.GetClass(c, a...)
+	}},
+	"get_parent_class": {0, func(c classobj.Classes, a []*types.Value) (*types.Value, error) {
+		return classobj.GetParentClass(c, a...)
+	}},
+	"get_object_vars": {1, func(c classobj.Classes, a []*types.Value) (*types.Value, error) {
+		return classobj.GetObjectVars(c, a[0])
+	}},
+	"get_class_methods": {1, func(c classobj.Classes, a []*types.Value) (*types.Value, error) {
+		return classobj.GetClassMethods(c, a[0])
+	}},
+	"is_a": {2, func(c classobj.Classes, a []*types.Value) (*types.Value, error) {
+		return classobj.IsA(c, a[0], a[1], a[2:]...)
+	}},
+	"is_subclass_of": {2, func(c classobj.Classes, a []*types.Value) (*types.Value, error) {
+		return classobj.IsSubclassOf(c, a[0], a[1], a[2:]...)
+	}},
+}
+
+// builtin wraps the function with an argument count check and passes it
+// the calling frame's classes. Errors of the classobj package are thrown
+// as the exception class they name.
+func (fn classFunction) builtin(name string) BuiltinFunction {
+	return func(vm *VM, args []*types.Value) (*types.Value, error) {
+		if len(args) < fn.required {
+			return nil, fmt.Errorf("%s() expects at least %d argument(s), %d given", name, fn.required, len(args))
+		}
+		result, err := fn.call(frameClasses{vm, vm.currentFrame()}, derefArgs(args))
+		if e, ok := err.(*classobj.Error); ok {
+			return nil, vm.ThrowError(e.Class, "%s", e.Message)
+		}
+		return result, err
+	}
+}
@@ -0,0 +1,55 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestBuiltin_ClassExistsAutoloads(t *testing.T) {
+	vm := New()
+	var loaded []string
+	vm.RegisterAutoloader(func(name string) error {
+		loaded = append(loaded, name)
+		vm.classes[name] = types.NewClassEntry(name)
+		return nil
+	})
+
+	result, err := vm.builtins["class_exists"](vm, []*types.Value{types.NewString("Lazy"), types.NewBool(false)})
+	if err != nil || result.ToBool() || len(loaded) != 0 {
+		t.Fatalf("Expected class_exists(autoload: false) not to load Lazy, got %v (loaded %v)", result, loaded)
+	}
+	result, err = vm.builtins["class_exists"](vm, []*types.Value{types.NewString("Lazy")})
+	if err != nil || !result.ToBool() || len(loaded) != 1 {
+		t.Errorf("Expected class_exists() to autoload Lazy, got %v (loaded %v)", result, loaded)
+	}
+}
+
+func TestBuiltin_GetObjectVarsUsesCallingScope(t *testing.T) {
+	vm := New()
+	class := types.NewClassEntry("Point")
+	class.Properties["x"] = &types.PropertyDef{Name: "x", Visibility: types.VisibilityPublic, DeclaringClass: "Point"}
+	class.Properties["y"] = &types.PropertyDef{Name: "y", Visibility: types.VisibilityPrivate, DeclaringClass: "Point"}
+	vm.classes["Point"] = class
+	obj := types.NewObject(types.NewObjectFromClass(class))
+
+	count := func() int {
+		result, err := vm.builtins["get_object_vars"](vm, []*types.Value{obj})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.ToArray().Len()
+	}
+	if got := count(); got != 1 {
+		t.Errorf("Expected 1 property outside the class, got %d", got)
+	}
+	frame := NewFrame(&CompiledFunction{Name: "f"})
+	frame.currentClass = class
+	vm.pushFrame(frame)
+	if got := count(); got != 2 {
+		t.Errorf("Expected 2 properties inside the class, got %d", got)
+	}
+
+	_, err := vm.builtins["get_object_vars"](vm, []*types.Value{types.NewInt(1)})
+	expectThrown(t, err, "TypeError", "get_object_vars(): Argument #1 ($object) must be of type object, int given")
+}
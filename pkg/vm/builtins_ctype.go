@@ -0,0 +1,45 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/stdlib/ctype"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Ctype Builtins
+// ============================================================================
+
+// ctypeFunctions maps the ctype functions to their pkg/stdlib/ctype
+// implementations
+var ctypeFunctions = map[string]func(text *types.Value) *types.Value{
+	"ctype_alnum":  ctype.CtypeAlnum,
+	"ctype_alpha":  ctype.CtypeAlpha,
+	"ctype_cntrl":  ctype.CtypeCntrl,
+	"ctype_digit":  ctype.CtypeDigit,
+	"ctype_graph":  ctype.CtypeGraph,
+	"ctype_lower":  ctype.CtypeLower,
+	"ctype_print":  ctype.CtypePrint,
+	"ctype_punct":  ctype.CtypePunct,
+	"ctype_space":  ctype.CtypeSpace,
+	"ctype_upper":  ctype.CtypeUpper,
+	"ctype_xdigit": ctype.CtypeXdigit,
+}
+
+// registerCtypeBuiltins registers the ctype functions. Arguments other
+// than strings are deprecated, as of PHP 8.1.
+func (vm *VM) registerCtypeBuiltins() {
+	for name, fn := range ctypeFunctions {
+		vm.RegisterBuiltin(name, func(vm *VM, args []*types.Value) (*types.Value, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("%s() expects exactly 1 argument, %d given", name, len(args))
+			}
+			text := args[0].Deref()
+			if !text.IsString() {
+				vm.deprecated("%s(): Argument of type %s will be interpreted as string in the future", name, text.TypeName())
+			}
+			return fn(text), nil
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package vm
+
+import (
+	"fmt"
+	goruntime "runtime"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/stdlib/curl"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// curl Builtins
+// ============================================================================
+
+// curlFunction is a curl builtin with its minimum argument count
+type curlFunction struct {
+	required int
+	call     func(vm *VM, args []*types.Value) (*types.Value, error)
+}
+
+// curlHandle returns the handle of a CurlHandle argument
+func (vm *VM) curlHandle(function string, value *types.Value) (*curl.Handle, error) {
+	if value.Type() == types.TypeObject {
+		if h, ok := value.ToObject().Internal.(*curl.Handle); ok {
+			return h, nil
+		}
+	}
+	return nil, vm.ThrowError("TypeError", "%s(): Argument #1 ($handle) must be of type CurlHandle, %s given", function, value.TypeName())
+}
+
+// newCurlHandle wraps a handle in a CurlHandle object
+func (vm *VM) newCurlHandle(h *curl.Handle) (*types.Value, error) {
+	obj, err := vm.newInstance(vm.classes["CurlHandle"])
+	if err != nil {
+		return nil, err
+	}
+	obj.Internal = h
+	return types.NewObject(obj), nil
+}
+
+// curlSetOpt sets an option, checking that callback options are callable
+func (vm *VM) curlSetOpt(function string, h *curl.Handle, option int64, value *types.Value) (bool, error) {
+	if option == curl.CURLOPT_WRITEFUNCTION || option == curl.CURLOPT_HEADERFUNCTION {
+		if !value.IsNull() && !vm.IsCallable(value) {
+			name := "CURLOPT_WRITEFUNCTION"
+			if option == curl.CURLOPT_HEADERFUNCTION {
+				name = "CURLOPT_HEADERFUNCTION"
+			}
+			return false, vm.ThrowError("TypeError", "%s(): Argument #3 ($value) must be a valid callback for option %s", function, name)
+		}
+	}
+	ok, err := h.SetOpt(option, value)
+	if e, isCurl := err.(*curl.Error); isCurl {
+		return false, vm.ThrowError(e.Class, "%s", strings.Replace(e.Message, "curl_setopt()", function+"()", 1))
+	}
+	return ok, err
+}
+
+// withCurlHandle adapts a function of the handle in its first argument
+func withCurlHandle(name string, fn func(vm *VM, h *curl.Handle, args []*types.Value) (*types.Value, error)) func(vm *VM, args []*types.Value) (*types.Value, error) {
+	return func(vm *VM, args []*types.Value) (*types.Value, error) {
+		h, err := vm.curlHandle(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return fn(vm, h, args)
+	}
+}
+
+// curlFunctions maps the curl functions to their implementations
+var curlFunctions = map[string]curlFunction{
+	"curl_init": {0, func(vm *VM, a []*types.Value) (*types.Value, error) {
+		url := ""
+		if len(a) > 0 && !a[0].IsNull() {
+			url = a[0].ToString()
+		}
+		return vm.newCurlHandle(curl.NewHandle(url))
+	}},
+	"curl_setopt": {3, withCurlHandle("curl_setopt", func(vm *VM, h *curl.Handle, a []*types.Value) (*types.Value, error) {
+		ok, err := vm.curlSetOpt("curl_setopt", h, a[1].ToInt(), a[2])
+		if err != nil {
+			return nil, err
+		}
+		return types.NewBool(ok), nil
+	})},
+	"curl_setopt_array": {2, withCurlHandle("curl_setopt_array", func(vm *VM, h *curl.Handle, a []*types.Value) (*types.Value, error) {
+		if a[1].Type() != types.TypeArray {
+			return nil, vm.ThrowError("TypeError", "curl_setopt_array(): Argument #2 ($options) must be of type array, %s given", a[1].TypeName())
+		}
+		result := true
+		var err error
+		a[1].ToArray().Each(func(option, value *types.Value) bool {
+			if option.Type() != types.TypeInt {
+				err = vm.ThrowError("TypeError", "curl_setopt_array(): Argument #2 ($options) must contain only int keys")
+				return false
+			}
+			result, err = vm.curlSetOpt("curl_setopt_array", h, option.ToInt(), value.Deref())
+			return result && err == nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return types.NewBool(result), nil
+	})},
+	"curl_exec": {1, withCurlHandle("curl_exec", func(vm *VM, h *curl.Handle, a []*types.Value) (*types.Value, error) {
+		return h.Exec(curl.Env{
+			Caller: vm,
+			Handle: a[0],
+			Output: func(data string) { vm.writeOutput([]byte(data)) },
+		})
+	})},
+	"curl_getinfo": {1, withCurlHandle("curl_getinfo", func(vm *VM, h *curl.Handle, a []*types.Value) (*types.Value, error) {
+		if len(a) > 1 && !a[1].IsNull() {
+			return h.GetInfo(a[1].ToInt()), nil
+		}
+		return types.NewArray(h.Info()), nil
+	})},
+	"curl_errno": {1, withCurlHandle("curl_errno", func(vm *VM, h *curl.Handle, a []*types.Value) (*types.Value, error) {
+		return types.NewInt(int64(h.Errno())), nil
+	})},
+	"curl_error": {1, withCurlHandle("curl_error", func(vm *VM, h *curl.Handle, a []*types.Value) (*types.Value, error) {
+		return types.NewString(h.Error()), nil
+	})},
+	"curl_reset": {1, withCurlHandle("curl_reset", func(vm *VM, h *curl.Handle, a []*types.Value) (*types.Value, error) {
+		h.Reset()
+		return types.NewNull(), nil
+	})},
+	"curl_copy_handle": {1, withCurlHandle("curl_copy_handle", func(vm *VM, h *curl.Handle, a []*types.Value) (*types.Value, error) {
+		return vm.newCurlHandle(h.Copy())
+	})},
+	// Handles are freed with their object; curl_close() has no effect
+	// since PHP 8.0
+	"curl_close": {1, withCurlHandle("curl_close", func(vm *VM, h *curl.Handle, a []*types.Value) (*types.Value, error) {
+		return types.NewNull(), nil
+	})},
+	"curl_strerror": {1, func(vm *VM, a []*types.Value) (*types.Value, error) {
+		return curl.Strerror(a[0]), nil
+	}},
+	"curl_version": {0, func(vm *VM, a []*types.Value) (*types.Value, error) {
+		return types.NewArray(curl.VersionInfo(goruntime.GOARCH + "-" + goruntime.GOOS)), nil
+	}},
+}
+
+// registerCurlBuiltins registers the curl functions and the CurlHandle
+// class, which cannot be instantiated directly
+func (vm *VM) registerCurlBuiltins() {
+	class := types.NewClassEntry("CurlHandle")
+	class.IsFinal = true
+	addNativeMethod(class, "__construct", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return nil, vm.ThrowError("Error", "Cannot directly construct CurlHandle, use curl_init() instead")
+	})
+	class.Constructor = class.Methods["__construct"]
+	class.Constructor.IsConstructor = true
+	vm.classes[class.Name] = class
+
+	for name, fn := range curlFunctions {
+		vm.RegisterBuiltin(name, func(vm *VM, args []*types.Value) (*types.Value, error) {
+			if len(args) < fn.required {
+				return nil, fmt.Errorf("%s() expects at least %d argument(s), %d given", name, fn.required, len(args))
+			}
+			return fn.call(vm, derefArgs(args))
+		})
+	}
+}
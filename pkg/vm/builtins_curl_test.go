@@ -0,0 +1,156 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/stdlib/curl"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// newEchoServer answers with the method, headers and body of the request;
+// /missing answers 404
+func newEchoServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.Error(w, "nope", http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Served", "yes")
+		fmt.Fprintf(w, "%s %s x=%s body=%s", r.Method, r.URL.Path, r.Header.Get("X-Test"), body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCurlBuiltins_Exec(t *testing.T) {
+	server := newEchoServer(t)
+	vm := New()
+	call := sessionCaller(t, vm)
+
+	ch := call("curl_init", types.NewString(server.URL+"/post"))
+	if ch.Type() != types.TypeObject || ch.ToObject().ClassName != "CurlHandle" {
+		t.Fatalf("curl_init() = %v", ch)
+	}
+	headers := types.NewEmptyArray()
+	headers.Append(types.NewString("X-Test: 1"))
+	options := types.NewEmptyArray()
+	options.Set(types.NewInt(curl.CURLOPT_RETURNTRANSFER), types.NewBool(true))
+	options.Set(types.NewInt(curl.CURLOPT_POSTFIELDS), types.NewString("a=b"))
+	options.Set(types.NewInt(curl.CURLOPT_HTTPHEADER), types.NewArray(headers))
+	if !call("curl_setopt_array", ch, types.NewArray(options)).ToBool() {
+		t.Fatal("curl_setopt_array() failed")
+	}
+
+	if result := call("curl_exec", ch); result.ToString() != "POST /post x=1 body=a=b" {
+		t.Errorf("curl_exec() = %v (%v)", result, call("curl_error", ch))
+	}
+	if code := call("curl_getinfo", ch, types.NewInt(curl.CURLINFO_HTTP_CODE)); code.ToInt() != 200 {
+		t.Errorf("curl_getinfo(CURLINFO_HTTP_CODE) = %v", code)
+	}
+	info := call("curl_getinfo", ch).ToArray()
+	if url, _ := info.Get(types.NewString("url")); url.ToString() != server.URL+"/post" {
+		t.Errorf("curl_getinfo()['url'] = %v", url)
+	}
+
+	// Without CURLOPT_RETURNTRANSFER the response is output
+	copied := call("curl_copy_handle", ch)
+	call("curl_setopt", copied, types.NewInt(curl.CURLOPT_RETURNTRANSFER), types.NewBool(false))
+	call("curl_setopt", copied, types.NewInt(curl.CURLOPT_HTTPGET), types.NewBool(true))
+	if result := call("curl_exec", copied); !result.ToBool() || vm.GetOutput() != "GET /post x=1 body=" {
+		t.Errorf("curl_exec() = %v, output %q", result, vm.GetOutput())
+	}
+	call("curl_close", copied)
+}
+
+func TestCurlBuiltins_WriteFunction(t *testing.T) {
+	server := newEchoServer(t)
+	vm := New()
+	call := sessionCaller(t, vm)
+	var received string
+	var handle *types.Value
+	vm.RegisterBuiltin("test_curl_write", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		handle = args[0]
+		received += args[1].ToString()
+		return types.NewInt(int64(len(args[1].ToString()))), nil
+	})
+
+	ch := call("curl_init", types.NewString(server.URL+"/cb"))
+	call("curl_setopt", ch, types.NewInt(curl.CURLOPT_WRITEFUNCTION), types.NewString("test_curl_write"))
+	if result := call("curl_exec", ch); !result.ToBool() || received != "GET /cb x= body=" {
+		t.Errorf("curl_exec() = %v, received %q", result, received)
+	}
+	if handle == nil || handle.ToObject() != ch.ToObject() {
+		t.Errorf("the callback receives the handle")
+	}
+
+	_, err := vm.CallCallable(types.NewString("curl_setopt"), []*types.Value{ch, types.NewInt(curl.CURLOPT_WRITEFUNCTION), types.NewString("missing_fn")})
+	expectThrown(t, err, "TypeError", "curl_setopt(): Argument #3 ($value) must be a valid callback for option CURLOPT_WRITEFUNCTION")
+}
+
+func TestCurlBuiltins_Errors(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+	ch := call("curl_init", types.NewString("http://127.0.0.1:1/"))
+	call("curl_setopt", ch, types.NewInt(curl.CURLOPT_RETURNTRANSFER), types.NewBool(true))
+	if result := call("curl_exec", ch); result.ToBool() {
+		t.Errorf("curl_exec() to a closed port = %v", result)
+	}
+	if errno := call("curl_errno", ch); errno.ToInt() != curl.CURLE_COULDNT_CONNECT {
+		t.Errorf("curl_errno() = %v", errno)
+	}
+	if message := call("curl_error", ch).ToString(); !strings.HasPrefix(message, "Failed to connect to 127.0.0.1 port 1") {
+		t.Errorf("curl_error() = %q", message)
+	}
+
+	_, err := vm.CallCallable(types.NewString("curl_exec"), []*types.Value{types.NewString("x")})
+	expectThrown(t, err, "TypeError", "curl_exec(): Argument #1 ($handle) must be of type CurlHandle, string given")
+	_, err = vm.CallCallable(types.NewString("curl_setopt"), []*types.Value{ch, types.NewInt(-5), types.NewInt(1)})
+	expectThrown(t, err, "ValueError", "curl_setopt(): Argument #2 ($option) is not a valid cURL option")
+}
+
+func TestFileGetContents_HTTPContext(t *testing.T) {
+	server := newEchoServer(t)
+	vm := New()
+	call := sessionCaller(t, vm)
+
+	options := types.NewArrayFromMap(map[interface{}]*types.Value{
+		"http": types.NewArray(types.NewArrayFromMap(map[interface{}]*types.Value{
+			"method":  types.NewString("PUT"),
+			"header":  types.NewString("X-Test: ctx\r\nContent-Type: text/plain"),
+			"content": types.NewString("payload"),
+		})),
+	})
+	ctx := call("stream_context_create", types.NewArray(options))
+	result := call("file_get_contents", types.NewString(server.URL+"/put"), types.NewBool(false), ctx)
+	if result.ToString() != "PUT /put x=ctx body=payload" {
+		t.Errorf("file_get_contents() = %v", result)
+	}
+	headers, _ := vm.GetGlobal("http_response_header")
+	if first, _ := headers.ToArray().Get(types.NewInt(0)); first == nil || first.ToString() != "HTTP/1.1 200 OK" {
+		t.Errorf("$http_response_header = %v", headers)
+	}
+
+	if result := call("file_get_contents", types.NewString(server.URL+"/missing")); result.ToBool() {
+		t.Errorf("file_get_contents() of a 404 = %v", result)
+	}
+	want := "file_get_contents(" + server.URL + "/missing): Failed to open stream: HTTP request failed! HTTP/1.1 404 Not Found"
+	if !strings.Contains(vm.GetOutput(), want) {
+		t.Errorf("expected %q, got %q", want, vm.GetOutput())
+	}
+
+	call("stream_context_set_option", ctx, types.NewString("http"), types.NewString("ignore_errors"), types.NewBool(true))
+	if result := call("file_get_contents", types.NewString(server.URL+"/missing"), types.NewBool(false), ctx); result.ToString() != "nope\n" {
+		t.Errorf("ignore_errors returns the body: %v", result)
+	}
+
+	stream := call("fopen", types.NewString(server.URL+"/stream"), types.NewString("r"))
+	if line := call("fgets", stream); line.ToString() != "GET /stream x= body=" {
+		t.Errorf("fgets() of an http stream = %v", line)
+	}
+}
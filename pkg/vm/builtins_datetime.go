@@ -0,0 +1,669 @@
+package vm
+
+import (
+	"math"
+	"time"
+
+	"github.com/krizos/php-go/pkg/stdlib/datetime"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Date/Time Classes
+// ============================================================================
+
+// dateFormatConstants are the format constants of DateTimeInterface
+var dateFormatConstants = []struct {
+	name   string
+	format string
+}{
+	{"ATOM", datetime.FormatATOM},
+	{"COOKIE", datetime.FormatCOOKIE},
+	{"ISO8601", datetime.FormatISO8601},
+	{"ISO8601_EXPANDED", datetime.FormatISO8601Expanded},
+	{"RFC822", datetime.FormatRFC822},
+	{"RFC850", datetime.FormatRFC850},
+	{"RFC1036", datetime.FormatRFC1036},
+	{"RFC1123", datetime.FormatRFC1123},
+	{"RFC7231", datetime.FormatRFC7231},
+	{"RFC2822", datetime.FormatRFC2822},
+	{"RFC3339", datetime.FormatRFC3339},
+	{"RFC3339_EXTENDED", datetime.FormatRFC3339Extended},
+	{"RSS", datetime.FormatRSS},
+	{"W3C", datetime.FormatW3C},
+}
+
+// registerDatetimeBuiltins registers DateTimeInterface, DateTime,
+// DateTimeImmutable, DateTimeZone, DateInterval and the procedural date
+// functions
+func (vm *VM) registerDatetimeBuiltins() {
+	iface := types.NewClassEntry("DateTimeInterface")
+	iface.IsInterface = true
+	addDateFormatConstants(iface)
+	vm.classes[iface.Name] = iface
+
+	for _, name := range []string{"DateTime", "DateTimeImmutable"} {
+		vm.classes[name] = dateTimeClass(name)
+	}
+
+	zone := types.NewClassEntry("DateTimeZone")
+	addNativeMethod(zone, "__construct", 1, timezoneConstruct)
+	zone.Constructor = zone.Methods["__construct"]
+	zone.Constructor.IsConstructor = true
+	addNativeMethod(zone, "getName", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		loc, err := vm.timezoneOf(this)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewString(loc.String()), nil
+	})
+	addNativeMethod(zone, "getOffset", 1, timezoneGetOffset)
+	addNativeMethod(zone, "__debugInfo", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		loc, err := vm.timezoneOf(this)
+		if err != nil {
+			return nil, err
+		}
+		info := types.NewEmptyArray()
+		info.Set(types.NewString("timezone_type"), types.NewInt(int64(datetime.ZoneType(loc))))
+		info.Set(types.NewString("timezone"), types.NewString(loc.String()))
+		return types.NewArray(info), nil
+	}).IsMagic = true
+	vm.classes[zone.Name] = zone
+
+	interval := types.NewClassEntry("DateInterval")
+	for _, name := range []string{"y", "m", "d", "h", "i", "s", "f", "invert", "days"} {
+		var def *types.Value
+		switch name {
+		case "f":
+			def = types.NewFloat(0)
+		case "days":
+			def = types.NewBool(false)
+		default:
+			def = types.NewInt(0)
+		}
+		interval.Properties[name] = &types.PropertyDef{
+			Name: name, Visibility: types.VisibilityPublic,
+			HasDefault: true, Default: def, DeclaringClass: interval.Name,
+		}
+	}
+	addNativeMethod(interval, "__construct", 1, intervalConstruct)
+	interval.Constructor = interval.Methods["__construct"]
+	interval.Constructor.IsConstructor = true
+	addNativeMethod(interval, "format", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		args, err := vm.dateArgs("DateInterval::format", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewString(intervalOf(this).Format(args[0].ToString())), nil
+	})
+	addNativeMethod(interval, "createFromDateString", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		args, err := vm.dateArgs("DateInterval::createFromDateString", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		iv, err := datetime.ParseRelativeInterval(args[0].ToString())
+		if err != nil {
+			return nil, vm.ThrowError("DateMalformedIntervalStringException",
+				"DateInterval::createFromDateString(): Unknown or bad format (%s)", args[0].ToString())
+		}
+		return vm.newInterval(iv), nil
+	}).IsStatic = true
+	vm.classes[interval.Name] = interval
+
+	vm.registerDateFunctions()
+}
+
+func addDateFormatConstants(class *types.ClassEntry) {
+	for _, c := range dateFormatConstants {
+		class.Constants[c.name] = &types.ClassConstant{Name: c.name, Value: types.NewString(c.format), Visibility: types.VisibilityPublic}
+	}
+}
+
+// dateTimeClass builds DateTime or DateTimeImmutable. Both share their
+// methods; modifications of a DateTimeImmutable return a modified copy.
+func dateTimeClass(name string) *types.ClassEntry {
+	class := types.NewClassEntry(name)
+	class.Interfaces = append(class.Interfaces, types.NewInterfaceEntry("DateTimeInterface"))
+	addDateFormatConstants(class)
+
+	addNativeMethod(class, "__construct", 2, dateTimeConstruct)
+	class.Constructor = class.Methods["__construct"]
+	class.Constructor.IsConstructor = true
+
+	addNativeMethod(class, "format", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		t, args, err := vm.dateTimeMethod(this, "format", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewString(datetime.Format(args[0].ToString(), t)), nil
+	})
+	addNativeMethod(class, "getTimestamp", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		t, _, err := vm.dateTimeMethod(this, "getTimestamp", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewInt(t.Unix()), nil
+	})
+	addNativeMethod(class, "getTimezone", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		t, _, err := vm.dateTimeMethod(this, "getTimezone", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return vm.newTimezone(t.Location()), nil
+	})
+	addNativeMethod(class, "getOffset", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		t, _, err := vm.dateTimeMethod(this, "getOffset", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		_, offset := t.Zone()
+		return types.NewInt(int64(offset)), nil
+	})
+
+	addNativeMethod(class, "setTimestamp", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		t, args, err := vm.dateTimeMethod(this, "setTimestamp", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return vm.modifiedDateTime(this, time.Unix(args[0].ToInt(), 0).In(t.Location())), nil
+	})
+	addNativeMethod(class, "setTimezone", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		t, args, err := vm.dateTimeMethod(this, "setTimezone", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		loc, err := vm.timezoneArg(dateClassName(vm, this)+"::setTimezone", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return vm.modifiedDateTime(this, t.In(loc)), nil
+	})
+	addNativeMethod(class, "setDate", 3, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		t, args, err := vm.dateTimeMethod(this, "setDate", args, 3)
+		if err != nil {
+			return nil, err
+		}
+		h, i, s := t.Clock()
+		return vm.modifiedDateTime(this, time.Date(int(args[0].ToInt()), time.Month(args[1].ToInt()), int(args[2].ToInt()),
+			h, i, s, t.Nanosecond(), t.Location())), nil
+	})
+	addNativeMethod(class, "setISODate", 3, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		t, args, err := vm.dateTimeMethod(this, "setISODate", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		// Week 1 is the week containing January 4th
+		jan4 := time.Date(int(args[0].ToInt()), time.January, 4, 0, 0, 0, 0, time.UTC)
+		weekday := int(jan4.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		day := 4 - (weekday - 1) + 7*(int(args[1].ToInt())-1) + intArg(args, 2, 1) - 1
+		h, i, s := t.Clock()
+		return vm.modifiedDateTime(this, time.Date(jan4.Year(), time.January, day, h, i, s, t.Nanosecond(), t.Location())), nil
+	})
+	addNativeMethod(class, "setTime", 4, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		t, args, err := vm.dateTimeMethod(this, "setTime", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		y, m, d := t.Date()
+		return vm.modifiedDateTime(this, time.Date(y, m, d, int(args[0].ToInt()), int(args[1].ToInt()),
+			intArg(args, 2, 0), intArg(args, 3, 0)*1000, t.Location())), nil
+	})
+	addNativeMethod(class, "modify", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		t, args, err := vm.dateTimeMethod(this, "modify", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		modified, err := datetime.Parse(args[0].ToString(), t)
+		if err != nil {
+			return nil, vm.ThrowError("DateMalformedStringException", "%s::modify(): %s", dateClassName(vm, this), err)
+		}
+		return vm.modifiedDateTime(this, modified), nil
+	})
+	for method, sign := range map[string]int{"add": 1, "sub": -1} {
+		addNativeMethod(class, method, 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+			t, args, err := vm.dateTimeMethod(this, method, args, 1)
+			if err != nil {
+				return nil, err
+			}
+			obj, err := vm.dateObjectArg(dateClassName(vm, this)+"::"+method, args, 0, "interval", "DateInterval")
+			if err != nil {
+				return nil, err
+			}
+			return vm.modifiedDateTime(this, intervalOf(obj).Add(t, sign)), nil
+		})
+	}
+	addNativeMethod(class, "diff", 2, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		t, args, err := vm.dateTimeMethod(this, "diff", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		obj, err := vm.dateObjectArg(dateClassName(vm, this)+"::diff", args, 0, "targetObject", "DateTimeInterface")
+		if err != nil {
+			return nil, err
+		}
+		target, err := vm.dateTimeOf(obj)
+		if err != nil {
+			return nil, err
+		}
+		iv := datetime.Diff(t, target)
+		if len(args) > 1 && args[1].ToBool() {
+			iv.Invert = false
+		}
+		return vm.newInterval(iv), nil
+	})
+	addNativeMethod(class, "__debugInfo", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		t, err := vm.dateTimeOf(this)
+		if err != nil {
+			return nil, err
+		}
+		info := types.NewEmptyArray()
+		info.Set(types.NewString("date"), types.NewString(datetime.Format("Y-m-d H:i:s.u", t)))
+		info.Set(types.NewString("timezone_type"), types.NewInt(int64(datetime.ZoneType(t.Location()))))
+		info.Set(types.NewString("timezone"), types.NewString(t.Location().String()))
+		return types.NewArray(info), nil
+	}).IsMagic = true
+
+	addNativeMethod(class, "createFromFormat", 3, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		args, err := vm.dateArgs(name+"::createFromFormat", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		loc, err := vm.timezoneArg(name+"::createFromFormat", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		t, err := datetime.ParseFromFormat(args[0].ToString(), args[1].ToString(), time.Now().In(loc))
+		if err != nil {
+			return types.NewBool(false), nil
+		}
+		return vm.newDateTime(name, t), nil
+	}).IsStatic = true
+	fromObject := func(method, source string) {
+		addNativeMethod(class, method, 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+			args, err := vm.dateArgs(name+"::"+method, args, 1)
+			if err != nil {
+				return nil, err
+			}
+			obj, err := vm.dateObjectArg(name+"::"+method, args, 0, "object", source)
+			if err != nil {
+				return nil, err
+			}
+			t, err := vm.dateTimeOf(obj)
+			if err != nil {
+				return nil, err
+			}
+			return vm.newDateTime(name, t), nil
+		}).IsStatic = true
+	}
+	fromObject("createFromInterface", "DateTimeInterface")
+	if name == "DateTime" {
+		fromObject("createFromImmutable", "DateTimeImmutable")
+	} else {
+		fromObject("createFromMutable", "DateTime")
+	}
+	return class
+}
+
+// dateClassName returns the built-in class of a date object, used to name
+// its methods in errors
+func dateClassName(vm *VM, obj *types.Object) string {
+	if vm.isInstanceOf(obj.ClassEntry, "DateTimeImmutable") {
+		return "DateTimeImmutable"
+	}
+	return "DateTime"
+}
+
+// dateArgs checks the argument count of a date function or method and
+// dereferences the arguments
+func (vm *VM) dateArgs(fn string, args []*types.Value, required int) ([]*types.Value, error) {
+	if len(args) < required {
+		noun := "arguments"
+		if required == 1 {
+			noun = "argument"
+		}
+		return nil, vm.ThrowError("ArgumentCountError", "%s() expects at least %d %s, %d given", fn, required, noun, len(args))
+	}
+	return derefArgs(args), nil
+}
+
+// dateTimeMethod prepares a DateTime method call: it returns the time of
+// the object and the checked arguments
+func (vm *VM) dateTimeMethod(this *types.Object, method string, args []*types.Value, required int) (time.Time, []*types.Value, error) {
+	args, err := vm.dateArgs(dateClassName(vm, this)+"::"+method, args, required)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	t, err := vm.dateTimeOf(this)
+	return t, args, err
+}
+
+// dateObjectArg returns an argument that must be an instance of class
+func (vm *VM) dateObjectArg(fn string, args []*types.Value, index int, param, class string) (*types.Object, error) {
+	arg := args[index]
+	if !arg.IsObject() || !vm.isInstanceOf(arg.ToObject().ClassEntry, class) {
+		return nil, vm.ThrowError("TypeError", "%s(): Argument #%d ($%s) must be of type %s, %s given",
+			fn, index+1, param, class, arg.TypeString())
+	}
+	return arg.ToObject(), nil
+}
+
+// timezoneArg returns an optional ?DateTimeZone argument, defaulting to
+// the default timezone
+func (vm *VM) timezoneArg(fn string, args []*types.Value, index int) (*time.Location, error) {
+	if index >= len(args) || args[index].IsNull() {
+		return datetime.DefaultTimezone(), nil
+	}
+	obj, err := vm.dateObjectArg(fn, args, index, "timezone", "DateTimeZone")
+	if err != nil {
+		return nil, err
+	}
+	return vm.timezoneOf(obj)
+}
+
+// dateTimeOf returns the time of a DateTime or DateTimeImmutable object
+func (vm *VM) dateTimeOf(obj *types.Object) (time.Time, error) {
+	t, ok := obj.Internal.(time.Time)
+	if !ok {
+		return time.Time{}, vm.ThrowError("Error", "The %s object has not been correctly initialized by its constructor",
+			dateClassName(vm, obj))
+	}
+	return t, nil
+}
+
+// timezoneOf returns the location of a DateTimeZone object
+func (vm *VM) timezoneOf(obj *types.Object) (*time.Location, error) {
+	loc, ok := obj.Internal.(*time.Location)
+	if !ok {
+		return nil, vm.ThrowError("Error", "The DateTimeZone object has not been correctly initialized by its constructor")
+	}
+	return loc, nil
+}
+
+// modifiedDateTime stores a modified time: a DateTime changes in place and
+// is returned itself, a DateTimeImmutable is left alone and a modified copy
+// returned. The time is replaced rather than mutated, so clones made by
+// the clone operator stay independent.
+func (vm *VM) modifiedDateTime(this *types.Object, t time.Time) *types.Value {
+	if vm.isInstanceOf(this.ClassEntry, "DateTimeImmutable") {
+		this = cloneObject(this)
+	}
+	this.Internal = t
+	return types.NewObject(this)
+}
+
+// newDateTime creates a DateTime or DateTimeImmutable object
+func (vm *VM) newDateTime(className string, t time.Time) *types.Value {
+	obj := types.NewObjectFromClass(vm.classes[className])
+	obj.Internal = t
+	return types.NewObject(obj)
+}
+
+// newTimezone creates a DateTimeZone object
+func (vm *VM) newTimezone(loc *time.Location) *types.Value {
+	obj := types.NewObjectFromClass(vm.classes["DateTimeZone"])
+	obj.Internal = loc
+	return types.NewObject(obj)
+}
+
+// newInterval creates a DateInterval object
+func (vm *VM) newInterval(iv datetime.Interval) *types.Value {
+	obj := types.NewObjectFromClass(vm.classes["DateInterval"])
+	setIntervalProperties(obj, iv)
+	return types.NewObject(obj)
+}
+
+// setIntervalProperties stores an interval in the public properties of a
+// DateInterval object, which is where PHP code reads and changes it
+func setIntervalProperties(obj *types.Object, iv datetime.Interval) {
+	set := func(name string, value *types.Value) {
+		obj.AddProperty(name, &types.Property{Value: value, Visibility: types.VisibilityPublic})
+	}
+	set("y", types.NewInt(int64(iv.Years)))
+	set("m", types.NewInt(int64(iv.Months)))
+	set("d", types.NewInt(int64(iv.Days)))
+	set("h", types.NewInt(int64(iv.Hours)))
+	set("i", types.NewInt(int64(iv.Minutes)))
+	set("s", types.NewInt(int64(iv.Seconds)))
+	set("f", types.NewFloat(float64(iv.Microseconds)/1e6))
+	invert := int64(0)
+	if iv.Invert {
+		invert = 1
+	}
+	set("invert", types.NewInt(invert))
+	if iv.TotalDays < 0 {
+		set("days", types.NewBool(false))
+	} else {
+		set("days", types.NewInt(int64(iv.TotalDays)))
+	}
+}
+
+// intervalOf reads the interval from the properties of a DateInterval
+func intervalOf(obj *types.Object) datetime.Interval {
+	field := func(name string) *types.Value {
+		if prop, ok := obj.Properties[name]; ok && prop.Value != nil {
+			return prop.Value.Deref()
+		}
+		return types.NewInt(0)
+	}
+	iv := datetime.Interval{
+		Years:        int(field("y").ToInt()),
+		Months:       int(field("m").ToInt()),
+		Days:         int(field("d").ToInt()),
+		Hours:        int(field("h").ToInt()),
+		Minutes:      int(field("i").ToInt()),
+		Seconds:      int(field("s").ToInt()),
+		Microseconds: int(math.Round(field("f").ToFloat() * 1e6)),
+		Invert:       field("invert").ToInt() != 0,
+		TotalDays:    -1,
+	}
+	if days := field("days"); days.IsInt() {
+		iv.TotalDays = int(days.ToInt())
+	}
+	return iv
+}
+
+// ============================================================================
+// Date/Time Methods
+// ============================================================================
+
+// DateTime::__construct(string $datetime = "now", ?DateTimeZone $timezone = null)
+func dateTimeConstruct(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	fn := dateClassName(vm, this) + "::__construct"
+	args = derefArgs(args)
+	text := "now"
+	if len(args) > 0 {
+		text = args[0].ToString()
+	}
+	loc, err := vm.timezoneArg(fn, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	t, err := datetime.Parse(text, time.Now().In(loc))
+	if err != nil {
+		return nil, vm.ThrowError("DateMalformedStringException", "%s(): %s", fn, err)
+	}
+	this.Internal = t
+	return types.NewNull(), nil
+}
+
+// DateTimeZone::__construct(string $timezone)
+func timezoneConstruct(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	args, err := vm.dateArgs("DateTimeZone::__construct", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	loc, ok := datetime.LoadTimezone(args[0].ToString())
+	if !ok {
+		return nil, vm.ThrowError("DateInvalidTimeZoneException", "DateTimeZone::__construct(): Unknown or bad timezone (%s)", args[0].ToString())
+	}
+	this.Internal = loc
+	return types.NewNull(), nil
+}
+
+// DateTimeZone::getOffset(DateTimeInterface $datetime): int
+func timezoneGetOffset(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	loc, err := vm.timezoneOf(this)
+	if err != nil {
+		return nil, err
+	}
+	args, err = vm.dateArgs("DateTimeZone::getOffset", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := vm.dateObjectArg("DateTimeZone::getOffset", args, 0, "datetime", "DateTimeInterface")
+	if err != nil {
+		return nil, err
+	}
+	t, err := vm.dateTimeOf(obj)
+	if err != nil {
+		return nil, err
+	}
+	_, offset := t.In(loc).Zone()
+	return types.NewInt(int64(offset)), nil
+}
+
+// DateInterval::__construct(string $duration)
+func intervalConstruct(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	args, err := vm.dateArgs("DateInterval::__construct", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := datetime.ParseInterval(args[0].ToString())
+	if err != nil {
+		return nil, vm.ThrowError("DateMalformedIntervalStringException", "DateInterval::__construct(): %s", err)
+	}
+	setIntervalProperties(this, iv)
+	return types.NewNull(), nil
+}
+
+// ============================================================================
+// Procedural Date Functions
+// ============================================================================
+
+// dateFunctions wrap the stdlib date functions
+var dateFunctions = []struct {
+	name     string
+	required int
+	call     func(args []*types.Value) *types.Value
+}{
+	{"time", 0, func(args []*types.Value) *types.Value { return datetime.Time() }},
+	{"microtime", 0, func(args []*types.Value) *types.Value { return datetime.Microtime(args...) }},
+	{"date", 1, func(args []*types.Value) *types.Value { return datetime.Date(args[0], args[1:]...) }},
+	{"gmdate", 1, func(args []*types.Value) *types.Value { return datetime.Gmdate(args[0], args[1:]...) }},
+	{"mktime", 0, func(args []*types.Value) *types.Value { return datetime.Mktime(args...) }},
+	{"gmmktime", 0, func(args []*types.Value) *types.Value { return datetime.Gmmktime(args...) }},
+	{"strtotime", 1, func(args []*types.Value) *types.Value { return datetime.Strtotime(args[0], args[1:]...) }},
+	{"checkdate", 3, func(args []*types.Value) *types.Value { return datetime.Checkdate(args[0], args[1], args[2]) }},
+	{"getdate", 0, func(args []*types.Value) *types.Value { return datetime.Getdate(args...) }},
+	{"localtime", 0, func(args []*types.Value) *types.Value { return datetime.Localtime(args...) }},
+}
+
+// dateMethodAliases are procedural functions calling a method of their
+// first argument
+var dateMethodAliases = []struct {
+	name   string
+	class  string
+	method string
+}{
+	{"date_format", "DateTimeInterface", "format"},
+	{"date_modify", "DateTime", "modify"},
+	{"date_add", "DateTime", "add"},
+	{"date_sub", "DateTime", "sub"},
+	{"date_diff", "DateTimeInterface", "diff"},
+	{"date_timestamp_get", "DateTimeInterface", "getTimestamp"},
+	{"date_timestamp_set", "DateTime", "setTimestamp"},
+	{"date_timezone_get", "DateTimeInterface", "getTimezone"},
+	{"date_timezone_set", "DateTime", "setTimezone"},
+	{"date_offset_get", "DateTimeInterface", "getOffset"},
+	{"date_date_set", "DateTime", "setDate"},
+	{"date_time_set", "DateTime", "setTime"},
+	{"timezone_name_get", "DateTimeZone", "getName"},
+	{"timezone_offset_get", "DateTimeZone", "getOffset"},
+	{"date_interval_format", "DateInterval", "format"},
+}
+
+func (vm *VM) registerDateFunctions() {
+	for _, fn := range dateFunctions {
+		vm.RegisterBuiltin(fn.name, func(vm *VM, args []*types.Value) (*types.Value, error) {
+			args, err := vm.dateArgs(fn.name, args, fn.required)
+			if err != nil {
+				return nil, err
+			}
+			return fn.call(args), nil
+		})
+	}
+
+	for _, alias := range dateMethodAliases {
+		vm.RegisterBuiltin(alias.name, func(vm *VM, args []*types.Value) (*types.Value, error) {
+			args, err := vm.dateArgs(alias.name, args, 1)
+			if err != nil {
+				return nil, err
+			}
+			obj, err := vm.dateObjectArg(alias.name, args, 0, "object", alias.class)
+			if err != nil {
+				return nil, err
+			}
+			return vm.CallCallable(callableMethod(obj, alias.method), args[1:])
+		})
+	}
+
+	// The date_create family returns false instead of throwing on
+	// malformed strings
+	for name, class := range map[string]string{"date_create": "DateTime", "date_create_immutable": "DateTimeImmutable"} {
+		vm.RegisterBuiltin(name, func(vm *VM, args []*types.Value) (*types.Value, error) {
+			obj := types.NewObjectFromClass(vm.classes[class])
+			if _, err := dateTimeConstruct(vm, obj, args); err != nil {
+				if thrown, ok := err.(*ThrowableError); ok && thrown.Object.ClassEntry.Name == "DateMalformedStringException" {
+					return types.NewBool(false), nil
+				}
+				return nil, err
+			}
+			return types.NewObject(obj), nil
+		})
+	}
+	for name, class := range map[string]string{"date_create_from_format": "DateTime", "date_create_immutable_from_format": "DateTimeImmutable"} {
+		vm.RegisterBuiltin(name, func(vm *VM, args []*types.Value) (*types.Value, error) {
+			return vm.CallCallable(types.NewString(class+"::createFromFormat"), args)
+		})
+	}
+	vm.RegisterBuiltin("timezone_open", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		args, err := vm.dateArgs("timezone_open", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		loc, ok := datetime.LoadTimezone(args[0].ToString())
+		if !ok {
+			vm.warning("timezone_open(): Unknown or bad timezone (%s)", args[0].ToString())
+			return types.NewBool(false), nil
+		}
+		return vm.newTimezone(loc), nil
+	})
+	vm.RegisterBuiltin("date_interval_create_from_date_string", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return vm.CallCallable(types.NewString("DateInterval::createFromDateString"), args)
+	})
+
+	vm.RegisterBuiltin("date_default_timezone_get", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return types.NewString(datetime.DefaultTimezone().String()), nil
+	})
+	// Only timezone identifiers are accepted, not offsets
+	vm.RegisterBuiltin("date_default_timezone_set", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		args, err := vm.dateArgs("date_default_timezone_set", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		loc, ok := datetime.LoadTimezone(args[0].ToString())
+		if !ok || datetime.ZoneType(loc) == datetime.ZoneTypeOffset {
+			return types.NewBool(false), nil
+		}
+		datetime.SetDefaultTimezone(loc)
+		return types.NewBool(true), nil
+	})
+}
+
+// callableMethod returns the [$object, 'method'] callable
+func callableMethod(obj *types.Object, method string) *types.Value {
+	return types.NewArray(types.NewArrayFromSlice([]*types.Value{types.NewObject(obj), types.NewString(method)}))
+}
@@ -0,0 +1,248 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/stdlib/datetime"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func callDate(t *testing.T, vm *VM, name string, args ...*types.Value) *types.Value {
+	t.Helper()
+	result, err := vm.CallCallable(types.NewString(name), args)
+	if err != nil {
+		t.Fatalf("%s() failed: %v", name, err)
+	}
+	return result
+}
+
+func callDateMethod(t *testing.T, obj *types.Value, method string, args ...*types.Value) *types.Value {
+	t.Helper()
+	vm := New()
+	result, err := vm.CallCallable(callableArray(obj, method), args)
+	if err != nil {
+		t.Fatalf("%s() failed: %v", method, err)
+	}
+	return result
+}
+
+func TestDateTime_ConstructAndFormat(t *testing.T) {
+	vm := New()
+	zone := callDate(t, vm, "timezone_open", types.NewString("Europe/Prague"))
+	dt := callDate(t, vm, "date_create", types.NewString("2024-07-04 09:05:03.25"), zone)
+	if !dt.IsObject() || dt.ToObject().ClassEntry.Name != "DateTime" {
+		t.Fatalf("Expected a DateTime, got %v", dt)
+	}
+	if !vm.isInstanceOf(dt.ToObject().ClassEntry, "DateTimeInterface") {
+		t.Error("Expected DateTime to implement DateTimeInterface")
+	}
+
+	format := vm.classes["DateTime"].Constants["RFC3339_EXTENDED"].Value
+	if got := callDateMethod(t, dt, "format", format).ToString(); got != "2024-07-04T09:05:03.250+02:00" {
+		t.Errorf("format(RFC3339_EXTENDED) = %q", got)
+	}
+	if got := callDateMethod(t, dt, "getOffset").ToInt(); got != 7200 {
+		t.Errorf("getOffset() = %d, want 7200", got)
+	}
+	if got := callDate(t, vm, "date_timestamp_get", dt).ToInt(); got != 1720076703 {
+		t.Errorf("date_timestamp_get() = %d", got)
+	}
+	tz := callDateMethod(t, dt, "getTimezone")
+	if got := callDateMethod(t, tz, "getName").ToString(); got != "Europe/Prague" {
+		t.Errorf("getTimezone()->getName() = %q", got)
+	}
+
+	// A timezone in the string wins over the argument
+	dt = callDate(t, vm, "date_create", types.NewString("2024-01-01 12:00 +05:00"), zone)
+	if got := callDate(t, vm, "date_format", dt, types.NewString("e P")).ToString(); got != "+05:00 +05:00" {
+		t.Errorf("timezone from the string = %q", got)
+	}
+
+	if got := callDate(t, vm, "date_create", types.NewString("not a date")); !got.IsBool() || got.ToBool() {
+		t.Errorf("date_create() of a malformed string = %v, want false", got)
+	}
+}
+
+func TestDateTime_MalformedStringThrows(t *testing.T) {
+	vm := New()
+	obj := types.NewObjectFromClass(vm.classes["DateTimeImmutable"])
+	_, err := dateTimeConstruct(vm, obj, []*types.Value{types.NewString("2024-01-01 2024-01-02")})
+
+	throwable, ok := err.(*ThrowableError)
+	if !ok || throwable.Object.ClassEntry.Name != "DateMalformedStringException" {
+		t.Fatalf("Expected a DateMalformedStringException, got %v", err)
+	}
+	if !vm.isInstanceOf(throwable.Object.ClassEntry, "DateException") {
+		t.Error("Expected DateMalformedStringException to extend DateException")
+	}
+	msg := throwableProperty(throwable.Object, "message").ToString()
+	if !strings.HasPrefix(msg, "DateTimeImmutable::__construct(): Failed to parse time string (2024-01-01 2024-01-02) at position 11 (2)") {
+		t.Errorf("Unexpected message %q", msg)
+	}
+
+	zone := types.NewObjectFromClass(vm.classes["DateTimeZone"])
+	_, err = timezoneConstruct(vm, zone, []*types.Value{types.NewString("Mars/Olympus")})
+	if throwable, ok := err.(*ThrowableError); !ok || throwable.Object.ClassEntry.Name != "DateInvalidTimeZoneException" {
+		t.Errorf("Expected a DateInvalidTimeZoneException, got %v", err)
+	}
+}
+
+func TestDateTime_MutableAndImmutable(t *testing.T) {
+	vm := New()
+	start := types.NewString("2024-01-31 10:00:00")
+
+	mutable := callDate(t, vm, "date_create", start)
+	result := callDateMethod(t, mutable, "modify", types.NewString("+1 month"))
+	if result.ToObject() != mutable.ToObject() {
+		t.Error("Expected DateTime::modify() to return the same object")
+	}
+	if got := callDate(t, vm, "date_format", mutable, types.NewString("Y-m-d")).ToString(); got != "2024-03-02" {
+		t.Errorf("DateTime after modify = %s, want 2024-03-02", got)
+	}
+
+	immutable := callDate(t, vm, "date_create_immutable", start)
+	result = callDateMethod(t, immutable, "setDate", types.NewInt(2020), types.NewInt(2), types.NewInt(29))
+	if result.ToObject() == immutable.ToObject() {
+		t.Error("Expected DateTimeImmutable::setDate() to return a new object")
+	}
+	if got := callDate(t, vm, "date_format", immutable, types.NewString("Y-m-d H:i")).ToString(); got != "2024-01-31 10:00" {
+		t.Errorf("DateTimeImmutable changed to %s", got)
+	}
+	if got := callDate(t, vm, "date_format", result, types.NewString("Y-m-d H:i")).ToString(); got != "2020-02-29 10:00" {
+		t.Errorf("setDate() result = %s", got)
+	}
+
+	result = callDateMethod(t, immutable, "setTime", types.NewInt(23), types.NewInt(59), types.NewInt(1), types.NewInt(5))
+	if got := callDate(t, vm, "date_format", result, types.NewString("H:i:s.u")).ToString(); got != "23:59:01.000005" {
+		t.Errorf("setTime() result = %s", got)
+	}
+	result = callDateMethod(t, immutable, "setISODate", types.NewInt(2024), types.NewInt(1))
+	if got := callDate(t, vm, "date_format", result, types.NewString("Y-m-d D")).ToString(); got != "2024-01-01 Mon" {
+		t.Errorf("setISODate(2024, 1) = %s", got)
+	}
+
+	fromMutable := callDate(t, vm, "DateTimeImmutable::createFromMutable", mutable)
+	if fromMutable.ToObject().ClassEntry.Name != "DateTimeImmutable" {
+		t.Errorf("createFromMutable() returned %s", fromMutable.ToObject().ClassEntry.Name)
+	}
+}
+
+func TestDateTime_IntervalsAndDiff(t *testing.T) {
+	vm := New()
+	interval := types.NewObjectFromClass(vm.classes["DateInterval"])
+	if _, err := intervalConstruct(vm, interval, []*types.Value{types.NewString("P1M2DT3H")}); err != nil {
+		t.Fatal(err)
+	}
+	if got := callDateMethod(t, types.NewObject(interval), "format", types.NewString("%m %d %h %a")).ToString(); got != "1 2 3 (unknown)" {
+		t.Errorf("DateInterval::format() = %q", got)
+	}
+
+	dt := callDate(t, vm, "date_create_immutable", types.NewString("2024-01-01 00:00:00"))
+	later := callDateMethod(t, dt, "add", types.NewObject(interval))
+	if got := callDate(t, vm, "date_format", later, types.NewString("Y-m-d H:i")).ToString(); got != "2024-02-03 03:00" {
+		t.Errorf("add(P1M2DT3H) = %s", got)
+	}
+	earlier := callDateMethod(t, dt, "sub", types.NewObject(interval))
+	if got := callDate(t, vm, "date_format", earlier, types.NewString("Y-m-d H:i")).ToString(); got != "2023-11-28 21:00" {
+		t.Errorf("sub(P1M2DT3H) = %s", got)
+	}
+
+	diff := callDate(t, vm, "date_diff", later, dt)
+	if got := callDate(t, vm, "date_interval_format", diff, types.NewString("%R%m %d %h %a")).ToString(); got != "-1 2 3 33" {
+		t.Errorf("date_diff() = %q", got)
+	}
+	if days := diff.ToObject().Properties["days"].Value; days.ToInt() != 33 {
+		t.Errorf("DateInterval->days = %v, want 33", days)
+	}
+
+	// Changing the public properties changes the interval
+	interval.Properties["d"].Value = types.NewInt(10)
+	later = callDateMethod(t, dt, "add", types.NewObject(interval))
+	if got := callDate(t, vm, "date_format", later, types.NewString("Y-m-d")).ToString(); got != "2024-02-11" {
+		t.Errorf("add() after changing d = %s", got)
+	}
+
+	_, err := intervalConstruct(vm, types.NewObjectFromClass(vm.classes["DateInterval"]), []*types.Value{types.NewString("P1X")})
+	throwable, ok := err.(*ThrowableError)
+	if !ok || throwable.Object.ClassEntry.Name != "DateMalformedIntervalStringException" {
+		t.Fatalf("Expected a DateMalformedIntervalStringException, got %v", err)
+	}
+	if msg := throwableProperty(throwable.Object, "message").ToString(); msg != "DateInterval::__construct(): Unknown or bad format (P1X)" {
+		t.Errorf("Unexpected message %q", msg)
+	}
+
+	// add() requires a DateInterval
+	if _, err := vm.CallCallable(callableArray(dt, "add"), []*types.Value{types.NewInt(1)}); err == nil {
+		t.Error("Expected add(1) to throw a TypeError")
+	}
+}
+
+func TestDateTime_CreateFromFormat(t *testing.T) {
+	vm := New()
+	dt := callDate(t, vm, "DateTime::createFromFormat", types.NewString("!d/m/Y"), types.NewString("15/08/2023"))
+	if !dt.IsObject() || dt.ToObject().ClassEntry.Name != "DateTime" {
+		t.Fatalf("Expected a DateTime, got %v", dt)
+	}
+	if got := callDate(t, vm, "date_format", dt, types.NewString("Y-m-d H:i:s")).ToString(); got != "2023-08-15 00:00:00" {
+		t.Errorf("createFromFormat() = %s", got)
+	}
+
+	bad := callDate(t, vm, "date_create_immutable_from_format", types.NewString("Y-m-d"), types.NewString("15/08/2023"))
+	if !bad.IsBool() || bad.ToBool() {
+		t.Errorf("createFromFormat() of a mismatching string = %v, want false", bad)
+	}
+}
+
+func TestDateTime_DebugInfo(t *testing.T) {
+	vm := New()
+	dt := callDate(t, vm, "date_create", types.NewString("2024-01-02 03:04:05 UTC"))
+	props, err := vm.DebugProperties(dt.ToObject())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shown []string
+	for _, prop := range props {
+		shown = append(shown, prop.Key.ToString()+"="+prop.Value.ToString())
+	}
+	if got := strings.Join(shown, ","); got != "date=2024-01-02 03:04:05.000000,timezone_type=3,timezone=UTC" {
+		t.Errorf("DateTime debug properties = %s", got)
+	}
+}
+
+func TestDateFunctions(t *testing.T) {
+	defer datetime.SetDefaultTimezone(datetime.DefaultTimezone())
+	vm := New()
+
+	if got := callDate(t, vm, "date_default_timezone_set", types.NewString("Asia/Tokyo")); !got.ToBool() {
+		t.Fatal("date_default_timezone_set(Asia/Tokyo) failed")
+	}
+	if got := callDate(t, vm, "date_default_timezone_get").ToString(); got != "Asia/Tokyo" {
+		t.Errorf("date_default_timezone_get() = %q", got)
+	}
+	if got := callDate(t, vm, "date", types.NewString("Y-m-d H:i T"), types.NewInt(0)).ToString(); got != "1970-01-01 09:00 JST" {
+		t.Errorf("date() in Asia/Tokyo = %q", got)
+	}
+	if got := callDate(t, vm, "gmdate", types.NewString("H:i"), types.NewInt(0)).ToString(); got != "00:00" {
+		t.Errorf("gmdate() = %q", got)
+	}
+	if got := callDate(t, vm, "strtotime", types.NewString("1970-01-02 09:00")).ToInt(); got != 86400 {
+		t.Errorf("strtotime() in Asia/Tokyo = %d, want 86400", got)
+	}
+	if got := callDate(t, vm, "strtotime", types.NewString("+1 day"), types.NewInt(0)).ToInt(); got != 86400 {
+		t.Errorf("strtotime('+1 day', 0) = %d", got)
+	}
+	if got := callDate(t, vm, "mktime", types.NewInt(9), types.NewInt(0), types.NewInt(0), types.NewInt(1), types.NewInt(2), types.NewInt(1970)).ToInt(); got != 86400 {
+		t.Errorf("mktime() in Asia/Tokyo = %d, want 86400", got)
+	}
+
+	if got := callDate(t, vm, "date_default_timezone_set", types.NewString("+02:00")); got.ToBool() {
+		t.Error("Expected date_default_timezone_set() to reject an offset")
+	}
+	if got := callDate(t, vm, "checkdate", types.NewInt(2), types.NewInt(30), types.NewInt(2024)); got.ToBool() {
+		t.Error("Expected checkdate(2, 30, 2024) to be false")
+	}
+	if _, err := vm.CallCallable(types.NewString("date"), nil); err == nil {
+		t.Error("Expected date() without arguments to throw")
+	}
+}
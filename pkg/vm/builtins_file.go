@@ -0,0 +1,234 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	stdfile "github.com/krizos/php-go/pkg/stdlib/file"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// File Builtins
+// ============================================================================
+
+// fileFunction adapts a pkg/stdlib/file function to a builtin, checking
+// the required argument count and dereferencing the arguments
+type fileFunction struct {
+	required int
+	call     func(args []*types.Value) *types.Value
+}
+
+// fileFunctions maps the filesystem and stream functions to their
+// pkg/stdlib/file implementations
+var fileFunctions = map[string]fileFunction{
+	"file_put_contents": {2, func(a []*types.Value) *types.Value { return stdfile.FilePutContents(a[0], a[1], a[2:]...) }},
+	"file":              {1, func(a []*types.Value) *types.Value { return stdfile.File(a[0], a[1:]...) }},
+	"fclose":            {1, func(a []*types.Value) *types.Value { return stdfile.Fclose(a[0]) }},
+	"fread":             {2, func(a []*types.Value) *types.Value { return stdfile.Fread(a[0], a[1]) }},
+	"fwrite":            {2, func(a []*types.Value) *types.Value { return stdfile.Fwrite(a[0], a[1], a[2:]...) }},
+	"fputs":             {2, func(a []*types.Value) *types.Value { return stdfile.Fwrite(a[0], a[1], a[2:]...) }},
+	"fgets":             {1, func(a []*types.Value) *types.Value { return stdfile.Fgets(a[0], a[1:]...) }},
+	"fgetc":             {1, func(a []*types.Value) *types.Value { return stdfile.Fgetc(a[0]) }},
+	"feof":              {1, func(a []*types.Value) *types.Value { return stdfile.Feof(a[0]) }},
+	"fseek":             {2, func(a []*types.Value) *types.Value { return stdfile.Fseek(a[0], a[1], a[2:]...) }},
+	"ftell":             {1, func(a []*types.Value) *types.Value { return stdfile.Ftell(a[0]) }},
+	"rewind":            {1, func(a []*types.Value) *types.Value { return stdfile.Rewind(a[0]) }},
+	"fflush":            {1, func(a []*types.Value) *types.Value { return stdfile.Fflush(a[0]) }},
+	"file_exists":       {1, func(a []*types.Value) *types.Value { return stdfile.FileExists(a[0]) }},
+	"is_file":           {1, func(a []*types.Value) *types.Value { return stdfile.IsFile(a[0]) }},
+	"is_dir":            {1, func(a []*types.Value) *types.Value { return stdfile.IsDir(a[0]) }},
+	"is_readable":       {1, func(a []*types.Value) *types.Value { return stdfile.IsReadable(a[0]) }},
+	"is_writable":       {1, func(a []*types.Value) *types.Value { return stdfile.IsWritable(a[0]) }},
+	"is_writeable":      {1, func(a []*types.Value) *types.Value { return stdfile.IsWritable(a[0]) }},
+	"filesize":          {1, func(a []*types.Value) *types.Value { return stdfile.Filesize(a[0]) }},
+	"filetype":          {1, func(a []*types.Value) *types.Value { return stdfile.Filetype(a[0]) }},
+	"mkdir":             {1, func(a []*types.Value) *types.Value { return stdfile.Mkdir(a[0], a[1:]...) }},
+	"rmdir":             {1, func(a []*types.Value) *types.Value { return stdfile.Rmdir(a[0]) }},
+	"scandir":           {1, func(a []*types.Value) *types.Value { return stdfile.Scandir(a[0], a[1:]...) }},
+	"glob":              {1, func(a []*types.Value) *types.Value { return stdfile.Glob(a[0], a[1:]...) }},
+	"dirname":           {1, func(a []*types.Value) *types.Value { return stdfile.Dirname(a[0], a[1:]...) }},
+	"basename":          {1, func(a []*types.Value) *types.Value { return stdfile.Basename(a[0], a[1:]...) }},
+	"pathinfo":          {1, func(a []*types.Value) *types.Value { return stdfile.Pathinfo(a[0], a[1:]...) }},
+	"realpath":          {1, func(a []*types.Value) *types.Value { return stdfile.Realpath(a[0]) }},
+	"unlink":            {1, func(a []*types.Value) *types.Value { return stdfile.Unlink(a[0]) }},
+	"rename":            {2, func(a []*types.Value) *types.Value { return stdfile.Rename(a[0], a[1]) }},
+	"copy":              {2, func(a []*types.Value) *types.Value { return stdfile.Copy(a[0], a[1]) }},
+
+	"stream_context_create":      {0, func(a []*types.Value) *types.Value { return stdfile.StreamContextCreate(a...) }},
+	"stream_context_get_options": {1, func(a []*types.Value) *types.Value { return stdfile.StreamContextGetOptions(a[0]) }},
+	"stream_context_set_option":  {2, func(a []*types.Value) *types.Value { return stdfile.StreamContextSetOption(a[0], a[1], a[2:]...) }},
+	"stream_context_get_params":  {1, func(a []*types.Value) *types.Value { return stdfile.StreamContextGetParams(a[0]) }},
+
+	"stream_set_blocking":    {2, func(a []*types.Value) *types.Value { return stdfile.StreamSetBlocking(a[0], a[1]) }},
+	"socket_set_blocking":    {2, func(a []*types.Value) *types.Value { return stdfile.StreamSetBlocking(a[0], a[1]) }},
+	"stream_set_timeout":     {2, func(a []*types.Value) *types.Value { return stdfile.StreamSetTimeout(a[0], a[1], a[2:]...) }},
+	"socket_set_timeout":     {2, func(a []*types.Value) *types.Value { return stdfile.StreamSetTimeout(a[0], a[1], a[2:]...) }},
+	"stream_get_contents":    {1, func(a []*types.Value) *types.Value { return stdfile.StreamGetContents(a[0], a[1:]...) }},
+	"stream_get_meta_data":   {1, func(a []*types.Value) *types.Value { return stdfile.StreamGetMetaData(a[0]) }},
+	"socket_get_status":      {1, func(a []*types.Value) *types.Value { return stdfile.StreamGetMetaData(a[0]) }},
+	"stream_socket_get_name": {2, func(a []*types.Value) *types.Value { return stdfile.SocketGetName(a[0], a[1]) }},
+	"stream_socket_shutdown": {2, func(a []*types.Value) *types.Value { return stdfile.SocketShutdown(a[0], a[1]) }},
+	"stream_socket_sendto": {2, func(a []*types.Value) *types.Value {
+		address := ""
+		if len(a) > 3 {
+			address = a[3].ToString()
+		}
+		return stdfile.SocketSendTo(a[0], a[1], address)
+	}},
+}
+
+// registerFileBuiltins registers the filesystem and stream functions
+func (vm *VM) registerFileBuiltins() {
+	for name, fn := range fileFunctions {
+		vm.RegisterBuiltin(name, fn.builtin(name))
+	}
+	vm.RegisterBuiltin("readfile", builtinReadfile)
+	vm.RegisterBuiltin("file_get_contents", builtinFileGetContents)
+	vm.RegisterBuiltin("fopen", builtinFopen)
+	vm.registerSocketBuiltins()
+	vm.defineStdioConstants()
+}
+
+// defineStdioConstants defines STDIN, STDOUT and STDERR, the streams of
+// the CLI (resources #1 to #3 in a fresh process). STDOUT writes to the
+// script output past the output buffers, in order with echo.
+func (vm *VM) defineStdioConstants() {
+	for _, stdio := range []struct {
+		name   string
+		stream *types.Stream
+	}{
+		{"STDIN", stdfile.NewStdioStream("php://stdin", "rb", os.Stdin, nil)},
+		{"STDOUT", stdfile.NewStdioStream("php://stdout", "wb", nil, scriptOutput{vm: vm})},
+		{"STDERR", stdfile.NewStdioStream("php://stderr", "wb", nil, os.Stderr)},
+	} {
+		vm.DefineConstant(stdio.name, types.NewResource(types.NewStreamResource(stdio.stream)))
+	}
+}
+
+// builtin wraps the function with an argument count check
+func (fn fileFunction) builtin(name string) BuiltinFunction {
+	return func(vm *VM, args []*types.Value) (*types.Value, error) {
+		if len(args) < fn.required {
+			return nil, fmt.Errorf("%s() expects at least %d argument(s), %d given", name, fn.required, len(args))
+		}
+		return fn.call(derefArgs(args)), nil
+	}
+}
+
+// readfile(string $filename): int|false
+// The file contents are written to the script output.
+func builtinReadfile(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("readfile() expects exactly 1 argument, 0 given")
+	}
+	data := stdfile.FileGetContents(args[0].Deref())
+	if data.Type() != types.TypeString {
+		return data, nil
+	}
+	vm.writeOutput([]byte(data.ToString()))
+	return types.NewInt(int64(len(data.ToString()))), nil
+}
+
+// file_get_contents(string $filename, bool $use_include_path = false, ?resource $context = null, ...): string|false
+// http:// and https:// URLs are fetched with the context's "http" options
+// and set $http_response_header in the calling scope.
+func builtinFileGetContents(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("file_get_contents() expects at least 1 argument(s), 0 given")
+	}
+	args = derefArgs(args)
+	filename := args[0].ToString()
+	if !stdfile.IsHTTPURL(filename) {
+		return stdfile.FileGetContents(args[0]), nil
+	}
+
+	resp, err := vm.openHTTP(filename, args, 2)
+	if err != nil {
+		vm.warning("file_get_contents(%s): Failed to open stream: %s", filename, err)
+		return types.NewBool(false), nil
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		vm.notice("file_get_contents(): Read of %d bytes failed", len(data))
+	}
+	return types.NewString(string(data)), nil
+}
+
+// fopen(string $filename, string $mode, bool $use_include_path = false, ?resource $context = null): resource|false
+// http:// and https:// URLs open a read-only stream on the response body.
+func builtinFopen(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("fopen() expects at least 2 argument(s), %d given", len(args))
+	}
+	args = derefArgs(args)
+	filename := args[0].ToString()
+	if !stdfile.IsHTTPURL(filename) {
+		stream, err := vm.openStream(filename, args[1].ToString())
+		if err != nil {
+			vm.warning("fopen(%s): Failed to open stream: %s", filename, err)
+			return types.NewBool(false), nil
+		}
+		return types.NewResource(types.NewStreamResource(stream)), nil
+	}
+
+	mode, err := types.ParseStreamMode(args[1].ToString())
+	if err == nil && mode.Write {
+		vm.warning("fopen(%s): Failed to open stream: HTTP wrapper does not support writeable connections", filename)
+		return types.NewBool(false), nil
+	}
+	resp, err := vm.openHTTP(filename, args, 3)
+	if err != nil {
+		vm.warning("fopen(%s): Failed to open stream: %s", filename, err)
+		return types.NewBool(false), nil
+	}
+	return stdfile.HTTPStream(filename, resp), nil
+}
+
+// openStream opens a file or php:// stream. php://stdout and php://output
+// write to the script output, php://stdout past the output buffers.
+func (vm *VM) openStream(filename string, mode string) (*types.Stream, error) {
+	switch strings.ToLower(filename) {
+	case "php://stdout":
+		return stdfile.NewStdioStream(filename, mode, nil, scriptOutput{vm: vm}), nil
+	case "php://output":
+		return stdfile.NewStdioStream(filename, mode, nil, scriptOutput{vm: vm, buffered: true}), nil
+	}
+	return stdfile.OpenStream(filename, mode)
+}
+
+// scriptOutput is a writer to the script output, through the ob_start()
+// buffers when buffered
+type scriptOutput struct {
+	vm       *VM
+	buffered bool
+}
+
+func (w scriptOutput) Write(data []byte) (int, error) {
+	if w.buffered {
+		w.vm.writeOutput(data)
+	} else {
+		w.vm.emitOutput(data)
+	}
+	return len(data), nil
+}
+
+// openHTTP sends the request of an http:// URL with the stream context
+// at args[contextArg], setting $http_response_header in the calling scope
+func (vm *VM) openHTTP(filename string, args []*types.Value, contextArg int) (*stdfile.HTTPResponse, error) {
+	var ctx *stdfile.StreamContext
+	if len(args) > contextArg {
+		ctx, _ = stdfile.ContextOf(args[contextArg])
+	}
+	resp, err := stdfile.OpenHTTP(filename, ctx)
+	headers := stdfile.ResponseHeaderArray(resp)
+	if frame := vm.currentFrame(); frame != nil {
+		frameScope{vm, frame}.AssignVariable("http_response_header", headers)
+	} else {
+		vm.SetGlobal("http_response_header", headers)
+	}
+	return resp, err
+}
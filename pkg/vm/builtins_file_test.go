@@ -0,0 +1,133 @@
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestFileBuiltins_StreamRoundTrip(t *testing.T) {
+	vm := New()
+	path := types.NewString(filepath.Join(t.TempDir(), "out.txt"))
+
+	call := func(name string, args ...*types.Value) *types.Value {
+		t.Helper()
+		result, err := vm.CallCallable(types.NewString(name), args)
+		if err != nil {
+			t.Fatalf("%s() failed: %v", name, err)
+		}
+		return result
+	}
+
+	handle := call("fopen", path, types.NewString("w"))
+	if handle.Type() != types.TypeResource {
+		t.Fatalf("fopen() = %v, want a resource", handle)
+	}
+	call("fwrite", handle, types.NewString("first\nsecond\n"))
+	call("fclose", handle)
+
+	handle = call("fopen", path, types.NewString("r"))
+	if line := call("fgets", handle); line.ToString() != "first\n" {
+		t.Errorf("fgets() = %q", line.ToString())
+	}
+	if pos := call("ftell", handle); pos.ToInt() != 6 {
+		t.Errorf("ftell() = %d, want 6", pos.ToInt())
+	}
+	call("fgets", handle)
+	if call("feof", handle).ToBool() {
+		t.Error("feof() should be false before reading past the end")
+	}
+	call("fgets", handle)
+	if !call("feof", handle).ToBool() {
+		t.Error("feof() should be true after reading past the end")
+	}
+	call("fclose", handle)
+
+	if !call("file_exists", path).ToBool() || !call("is_file", path).ToBool() {
+		t.Error("Expected the written file to exist")
+	}
+	if !call("unlink", path).ToBool() || call("file_exists", path).ToBool() {
+		t.Error("Expected unlink() to remove the file")
+	}
+}
+
+func TestFileBuiltins_ArgumentCount(t *testing.T) {
+	vm := New()
+	if _, err := vm.CallCallable(types.NewString("fopen"), []*types.Value{types.NewString("x")}); err == nil {
+		t.Error("Expected fopen() with one argument to fail")
+	}
+}
+
+func TestReadfile_WritesOutput(t *testing.T) {
+	vm := New()
+	path := filepath.Join(t.TempDir(), "page.txt")
+	os.WriteFile(path, []byte("hello"), 0644)
+
+	result, err := vm.CallCallable(types.NewString("readfile"), []*types.Value{types.NewString(path)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ToInt() != 5 || vm.GetOutput() != "hello" {
+		t.Errorf("readfile() = %v, output %q", result, vm.GetOutput())
+	}
+}
+
+func TestFopen_FailureWarning(t *testing.T) {
+	vm := New()
+	path := filepath.Join(t.TempDir(), "missing.txt")
+
+	result, err := vm.CallCallable(types.NewString("fopen"), []*types.Value{types.NewString(path), types.NewString("r")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "fopen(" + path + "): Failed to open stream: No such file or directory"
+	if result.ToBool() || !strings.Contains(vm.GetOutput(), want) {
+		t.Errorf("fopen() = %v, output %q, want a warning %q", result, vm.GetOutput(), want)
+	}
+}
+
+func TestFopen_ScriptOutput(t *testing.T) {
+	vm := New()
+	call := func(name string, args ...*types.Value) *types.Value {
+		t.Helper()
+		result, err := vm.CallCallable(types.NewString(name), args)
+		if err != nil {
+			t.Fatalf("%s() failed: %v", name, err)
+		}
+		return result
+	}
+
+	// php://output goes through the output buffers, php://stdout past them
+	stdout := call("fopen", types.NewString("php://stdout"), types.NewString("w"))
+	output := call("fopen", types.NewString("php://output"), types.NewString("w"))
+	call("ob_start")
+	call("fwrite", output, types.NewString("buffered"))
+	call("fwrite", stdout, types.NewString("direct,"))
+	call("ob_end_flush")
+	if got := vm.GetOutput(); got != "direct,buffered" {
+		t.Errorf("output = %q, want %q", got, "direct,buffered")
+	}
+}
+
+func TestStdioConstants(t *testing.T) {
+	vm := New()
+	for _, name := range []string{"STDIN", "STDOUT", "STDERR"} {
+		value, ok := vm.LookupConstant(name)
+		if !ok || value.Type() != types.TypeResource || value.ToResource().Type() != types.StreamResourceType {
+			t.Errorf("%s = %v, want a stream resource", name, value)
+		}
+	}
+
+	// STDOUT writes to the script output, in order with it
+	stdout, _ := vm.LookupConstant("STDOUT")
+	vm.writeOutput([]byte("a"))
+	if _, err := vm.CallCallable(types.NewString("fwrite"), []*types.Value{stdout, types.NewString("b")}); err != nil {
+		t.Fatal(err)
+	}
+	if vm.GetOutput() != "ab" {
+		t.Errorf("output = %q, want \"ab\"", vm.GetOutput())
+	}
+}
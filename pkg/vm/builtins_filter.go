@@ -0,0 +1,89 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/stdlib/filter"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Filter Builtins
+// ============================================================================
+
+// SetRequestInput sets the request data of an INPUT_* source (the query
+// string, the POST body, the cookies, ...) and the superglobal holding a
+// copy of it. filter_input() reads the data set here, so scripts changing
+// the superglobal do not change what it sees.
+func (vm *VM) SetRequestInput(source int64, data *types.Array) {
+	name, ok := filter.InputSuperglobals[source]
+	if !ok {
+		return
+	}
+	if vm.requestInput == nil {
+		vm.requestInput = make(map[int64]*types.Array)
+	}
+	vm.requestInput[source] = data
+	vm.SetGlobal(name, types.NewArray(data.Copy()))
+}
+
+// filter returns the filter of the VM's hooks: callbacks and __toString()
+// are called, warnings reported and input read from the request data
+func (vm *VM) filter() *filter.Filter {
+	return &filter.Filter{
+		Caller:   vm,
+		Warning:  func(message string) { vm.warning("%s", message) },
+		Stringer: vm.stringable,
+		Source: func(source int64) *types.Array {
+			return vm.requestInput[source]
+		},
+	}
+}
+
+// filterFunction is a filter builtin with its minimum argument count
+type filterFunction struct {
+	required int
+	call     func(f *filter.Filter, args []*types.Value) (*types.Value, error)
+}
+
+// filterFunctions maps the filter functions to their pkg/stdlib/filter
+// implementations
+var filterFunctions = map[string]filterFunction{
+	"filter_var": {1, func(f *filter.Filter, a []*types.Value) (*types.Value, error) {
+		return f.Var(a[0], a[1:]...)
+	}},
+	"filter_var_array": {1, func(f *filter.Filter, a []*types.Value) (*types.Value, error) {
+		return f.VarArray(a[0], a[1:]...)
+	}},
+	"filter_input": {2, func(f *filter.Filter, a []*types.Value) (*types.Value, error) {
+		return f.Input(a[0], a[1], a[2:]...)
+	}},
+	"filter_input_array": {1, func(f *filter.Filter, a []*types.Value) (*types.Value, error) {
+		return f.InputArray(a[0], a[1:]...)
+	}},
+	"filter_has_var": {2, func(f *filter.Filter, a []*types.Value) (*types.Value, error) {
+		return f.HasVar(a[0], a[1])
+	}},
+	"filter_list": {0, func(f *filter.Filter, a []*types.Value) (*types.Value, error) {
+		return filter.FilterList(), nil
+	}},
+	"filter_id": {1, func(f *filter.Filter, a []*types.Value) (*types.Value, error) {
+		return filter.FilterID(a[0]), nil
+	}},
+}
+
+// registerFilterBuiltins registers the filter functions
+func (vm *VM) registerFilterBuiltins() {
+	for name, fn := range filterFunctions {
+		vm.RegisterBuiltin(name, func(vm *VM, args []*types.Value) (*types.Value, error) {
+			if len(args) < fn.required {
+				return nil, fmt.Errorf("%s() expects at least %d argument(s), %d given", name, fn.required, len(args))
+			}
+			result, err := fn.call(vm.filter(), derefArgs(args))
+			if e, ok := err.(*filter.Error); ok {
+				return nil, vm.ThrowError(e.Class, "%s", e.Message)
+			}
+			return result, err
+		})
+	}
+}
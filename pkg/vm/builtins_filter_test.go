@@ -0,0 +1,78 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/stdlib/filter"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestFilterBuiltins_FilterVar(t *testing.T) {
+	vm := New()
+	call := func(name string, args ...*types.Value) *types.Value {
+		t.Helper()
+		result, err := vm.CallCallable(types.NewString(name), args)
+		if err != nil {
+			t.Fatalf("%s() failed: %v", name, err)
+		}
+		return result
+	}
+
+	if result := call("filter_var", types.NewString("0x1f"), types.NewInt(filter.FILTER_VALIDATE_INT), types.NewInt(filter.FILTER_FLAG_ALLOW_HEX)); result.ToInt() != 31 {
+		t.Errorf("filter_var('0x1f', FILTER_VALIDATE_INT, FILTER_FLAG_ALLOW_HEX) = %v", result)
+	}
+
+	def := types.NewEmptyArray()
+	def.Set(types.NewString("options"), types.NewString("soundex"))
+	if result := call("filter_var", types.NewString("Robert"), types.NewInt(filter.FILTER_CALLBACK), types.NewArray(def)); result.ToString() != "R163" {
+		t.Errorf("FILTER_CALLBACK with soundex = %v", result)
+	}
+
+	if result := call("filter_var", types.NewString("x"), types.NewInt(4242)); !result.IsBool() || result.ToBool() {
+		t.Errorf("unknown filter = %v, want false", result)
+	}
+	if output := vm.GetOutput(); !strings.Contains(output, "filter_var(): Unknown filter with ID 4242") {
+		t.Errorf("expected an unknown filter warning, got %q", output)
+	}
+
+	options := types.NewEmptyArray()
+	options.Set(types.NewString("decimal"), types.NewString(""))
+	def = types.NewEmptyArray()
+	def.Set(types.NewString("options"), types.NewArray(options))
+	_, err := vm.CallCallable(types.NewString("filter_var"), []*types.Value{types.NewString("1"), types.NewInt(filter.FILTER_VALIDATE_FLOAT), types.NewArray(def)})
+	expectThrown(t, err, "ValueError", `filter_var(): "decimal" option must be one character long`)
+
+	if id := call("filter_id", types.NewString("int")); id.ToInt() != filter.FILTER_VALIDATE_INT {
+		t.Errorf("filter_id('int') = %v", id)
+	}
+}
+
+func TestFilterBuiltins_FilterInputReadsRequestData(t *testing.T) {
+	vm := New()
+	get := types.NewEmptyArray()
+	get.Set(types.NewString("page"), types.NewString("2"))
+	vm.SetRequestInput(filter.INPUT_GET, get)
+
+	superglobal, ok := vm.GetGlobal("_GET")
+	if !ok || superglobal.ToArray().Len() != 1 {
+		t.Fatalf("$_GET = %v", superglobal)
+	}
+	// Changing $_GET does not change the request data
+	superglobal.ToArray().Set(types.NewString("page"), types.NewString("9"))
+
+	result, err := vm.CallCallable(types.NewString("filter_input"), []*types.Value{
+		types.NewInt(filter.INPUT_GET), types.NewString("page"), types.NewInt(filter.FILTER_VALIDATE_INT),
+	})
+	if err != nil || !result.IsInt() || result.ToInt() != 2 {
+		t.Errorf("filter_input(INPUT_GET, 'page', FILTER_VALIDATE_INT) = %v, %v", result, err)
+	}
+
+	has, _ := vm.CallCallable(types.NewString("filter_has_var"), []*types.Value{types.NewInt(filter.INPUT_POST), types.NewString("page")})
+	if has.ToBool() {
+		t.Errorf("filter_has_var(INPUT_POST, 'page') should be false")
+	}
+
+	_, err = vm.CallCallable(types.NewString("filter_input"), []*types.Value{types.NewInt(42), types.NewString("page")})
+	expectThrown(t, err, "ValueError", "filter_input(): Argument #1 ($type) must be an INPUT_* constant")
+}
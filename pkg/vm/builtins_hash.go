@@ -0,0 +1,217 @@
+package vm
+
+import (
+	"fmt"
+
+	stdhash "github.com/krizos/php-go/pkg/stdlib/hash"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Hash Builtins
+// ============================================================================
+
+// hashFunction adapts a pkg/stdlib/hash function to a builtin, checking
+// the required argument count and dereferencing the arguments
+type hashFunction struct {
+	required int
+	call     func(args []*types.Value) (*types.Value, error)
+}
+
+// hashFunctions maps the hash, encoding and password functions to their
+// pkg/stdlib/hash implementations
+var hashFunctions = map[string]hashFunction{
+	"hash": {2, func(a []*types.Value) (*types.Value, error) { return stdhash.Hash(a[0], a[1], a[2:]...) }},
+	"hash_file": {2, func(a []*types.Value) (*types.Value, error) {
+		return stdhash.HashFile(a[0], a[1], a[2:]...)
+	}},
+	"hash_hmac": {3, func(a []*types.Value) (*types.Value, error) {
+		return stdhash.HashHmac(a[0], a[1], a[2], a[3:]...)
+	}},
+	"hash_hmac_file": {3, func(a []*types.Value) (*types.Value, error) {
+		return stdhash.HashHmacFile(a[0], a[1], a[2], a[3:]...)
+	}},
+	"hash_pbkdf2": {4, func(a []*types.Value) (*types.Value, error) {
+		return stdhash.HashPbkdf2(a[0], a[1], a[2], a[3], a[4:]...)
+	}},
+	"hash_algos":      {0, total(func(a []*types.Value) *types.Value { return stdhash.HashAlgos() })},
+	"hash_hmac_algos": {0, total(func(a []*types.Value) *types.Value { return stdhash.HashHmacAlgos() })},
+
+	"md5":       {1, total(func(a []*types.Value) *types.Value { return stdhash.Md5(a[0], a[1:]...) })},
+	"md5_file":  {1, total(func(a []*types.Value) *types.Value { return stdhash.Md5File(a[0], a[1:]...) })},
+	"sha1":      {1, total(func(a []*types.Value) *types.Value { return stdhash.Sha1(a[0], a[1:]...) })},
+	"sha1_file": {1, total(func(a []*types.Value) *types.Value { return stdhash.Sha1File(a[0], a[1:]...) })},
+	"crc32":     {1, total(func(a []*types.Value) *types.Value { return stdhash.Crc32(a[0]) })},
+
+	"base64_encode": {1, total(func(a []*types.Value) *types.Value { return stdhash.Base64Encode(a[0]) })},
+	"base64_decode": {1, total(func(a []*types.Value) *types.Value { return stdhash.Base64Decode(a[0], a[1:]...) })},
+	"bin2hex":       {1, total(func(a []*types.Value) *types.Value { return stdhash.Bin2hex(a[0]) })},
+
+	"password_hash": {2, func(a []*types.Value) (*types.Value, error) {
+		return stdhash.PasswordHash(a[0], a[1], a[2:]...)
+	}},
+	"password_verify":   {2, total(func(a []*types.Value) *types.Value { return stdhash.PasswordVerify(a[0], a[1]) })},
+	"password_get_info": {1, total(func(a []*types.Value) *types.Value { return stdhash.PasswordGetInfo(a[0]) })},
+	"password_needs_rehash": {2, func(a []*types.Value) (*types.Value, error) {
+		return stdhash.PasswordNeedsRehash(a[0], a[1], a[2:]...)
+	}},
+	"password_algos": {0, total(func(a []*types.Value) *types.Value { return stdhash.PasswordAlgos() })},
+}
+
+// registerHashBuiltins registers the hash functions and the HashContext
+// class of incremental hashing. Errors of the hash package are thrown as
+// the exception class they name.
+func (vm *VM) registerHashBuiltins() {
+	for name, fn := range hashFunctions {
+		vm.RegisterBuiltin(name, fn.builtin(name))
+	}
+
+	vm.RegisterBuiltin("hash_equals", builtinHashEquals)
+	vm.RegisterBuiltin("hex2bin", builtinHex2bin)
+	vm.RegisterBuiltin("hash_init", builtinHashInit)
+	vm.RegisterBuiltin("hash_update", builtinHashUpdate)
+	vm.RegisterBuiltin("hash_update_file", builtinHashUpdateFile)
+	vm.RegisterBuiltin("hash_final", builtinHashFinal)
+	vm.RegisterBuiltin("hash_copy", builtinHashCopy)
+
+	class := types.NewClassEntry("HashContext")
+	class.IsFinal = true
+	addNativeMethod(class, "__construct", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return types.NewNull(), nil
+	}).Visibility = types.VisibilityPrivate
+	vm.classes[class.Name] = class
+}
+
+// builtin wraps the function with an argument count check
+func (fn hashFunction) builtin(name string) BuiltinFunction {
+	return func(vm *VM, args []*types.Value) (*types.Value, error) {
+		if len(args) < fn.required {
+			return nil, fmt.Errorf("%s() expects at least %d argument(s), %d given", name, fn.required, len(args))
+		}
+		result, err := fn.call(derefArgs(args))
+		return result, vm.hashError(err)
+	}
+}
+
+// hashError throws the errors of the hash package
+func (vm *VM) hashError(err error) error {
+	if e, ok := err.(*stdhash.Error); ok {
+		return vm.ThrowError(e.Class, "%s", e.Message)
+	}
+	return err
+}
+
+// hash_equals(string $known_string, string $user_string): bool
+func builtinHashEquals(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("hash_equals() expects exactly 2 arguments, %d given", len(args))
+	}
+	args = derefArgs(args)
+	for i, param := range []string{"known_string", "user_string"} {
+		if !args[i].IsString() {
+			return nil, vm.ThrowError("TypeError", "hash_equals(): Argument #%d ($%s) must be of type string, %s given",
+				i+1, param, args[i].TypeName())
+		}
+	}
+	return stdhash.HashEquals(args[0], args[1]), nil
+}
+
+// hex2bin(string $string): string|false
+func builtinHex2bin(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("hex2bin() expects exactly 1 argument, 0 given")
+	}
+	result, warning := stdhash.Hex2bin(args[0].Deref())
+	if warning != "" {
+		vm.warning("hex2bin(): %s", warning)
+	}
+	return result, nil
+}
+
+// hashContextArg returns the HashContext argument of fn
+func (vm *VM) hashContextArg(fn string, args []*types.Value) (*stdhash.Context, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("%s() expects at least 1 argument, 0 given", fn)
+	}
+	arg := args[0].Deref()
+	if !arg.IsObject() || arg.ToObject().ClassEntry == nil || arg.ToObject().ClassEntry.Name != "HashContext" {
+		return nil, vm.ThrowError("TypeError", "%s(): Argument #1 ($context) must be of type HashContext, %s given", fn, arg.TypeName())
+	}
+	ctx, ok := arg.ToObject().Internal.(*stdhash.Context)
+	if !ok {
+		return nil, vm.ThrowError("TypeError", "%s(): Argument #1 ($context) must be a valid, non-finalized HashContext", fn)
+	}
+	return ctx, nil
+}
+
+// newHashContext wraps a context in a HashContext object
+func (vm *VM) newHashContext(ctx *stdhash.Context) *types.Value {
+	obj := types.NewObjectFromClass(vm.classes["HashContext"])
+	obj.Internal = ctx
+	return types.NewObject(obj)
+}
+
+// hash_init(string $algo, int $flags = 0, string $key = "", array $options = []): HashContext
+func builtinHashInit(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("hash_init() expects at least 1 argument, 0 given")
+	}
+	args = derefArgs(args)
+	ctx, err := stdhash.HashInit(args[0], args[1:]...)
+	if err != nil {
+		return nil, vm.hashError(err)
+	}
+	return vm.newHashContext(ctx), nil
+}
+
+// hash_update(HashContext $context, string $data): true
+func builtinHashUpdate(vm *VM, args []*types.Value) (*types.Value, error) {
+	ctx, err := vm.hashContextArg("hash_update", args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < 2 {
+		return nil, fmt.Errorf("hash_update() expects exactly 2 arguments, %d given", len(args))
+	}
+	if err := ctx.Update(args[1].Deref().ToString()); err != nil {
+		return nil, vm.hashError(err)
+	}
+	return types.NewBool(true), nil
+}
+
+// hash_update_file(HashContext $context, string $filename, ?resource $stream_context = null): bool
+func builtinHashUpdateFile(vm *VM, args []*types.Value) (*types.Value, error) {
+	ctx, err := vm.hashContextArg("hash_update_file", args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < 2 {
+		return nil, fmt.Errorf("hash_update_file() expects at least 2 arguments, %d given", len(args))
+	}
+	result, err := ctx.UpdateFile(args[1].Deref())
+	return result, vm.hashError(err)
+}
+
+// hash_final(HashContext $context, bool $binary = false): string
+func builtinHashFinal(vm *VM, args []*types.Value) (*types.Value, error) {
+	ctx, err := vm.hashContextArg("hash_final", args)
+	if err != nil {
+		return nil, err
+	}
+	binary := len(args) > 1 && args[1].Deref().ToBool()
+	result, err := ctx.Final(binary)
+	return result, vm.hashError(err)
+}
+
+// hash_copy(HashContext $context): HashContext
+func builtinHashCopy(vm *VM, args []*types.Value) (*types.Value, error) {
+	ctx, err := vm.hashContextArg("hash_copy", args)
+	if err != nil {
+		return nil, err
+	}
+	copied, err := ctx.Copy()
+	if err != nil {
+		return nil, vm.hashError(err)
+	}
+	return vm.newHashContext(copied), nil
+}
@@ -0,0 +1,82 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestHashBuiltins(t *testing.T) {
+	vm := New()
+
+	call := func(name string, args ...*types.Value) *types.Value {
+		t.Helper()
+		result, err := vm.CallCallable(types.NewString(name), args)
+		if err != nil {
+			t.Fatalf("%s() failed: %v", name, err)
+		}
+		return result
+	}
+
+	if h := call("hash", types.NewString("crc32b"), types.NewString("hello")); h.ToString() != "3610a686" {
+		t.Errorf("hash('crc32b', 'hello') = %s, want 3610a686", h.ToString())
+	}
+	if s := call("base64_decode", call("base64_encode", types.NewString("php-go"))); s.ToString() != "php-go" {
+		t.Errorf("base64 round trip = %q", s.ToString())
+	}
+	if s := call("bin2hex", types.NewString("ab")); s.ToString() != "6162" {
+		t.Errorf("bin2hex('ab') = %s", s.ToString())
+	}
+
+	ctx := call("hash_init", types.NewString("md5"))
+	if !ctx.IsObject() || ctx.ToObject().ClassName != "HashContext" {
+		t.Fatalf("hash_init() = %v, want a HashContext", ctx)
+	}
+	call("hash_update", ctx, types.NewString("hel"))
+	copied := call("hash_copy", ctx)
+	call("hash_update", ctx, types.NewString("lo"))
+	if h := call("hash_final", ctx); h.ToString() != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("incremental md5('hello') = %s", h.ToString())
+	}
+	if h := call("hash_final", copied); h.ToString() != call("md5", types.NewString("hel")).ToString() {
+		t.Errorf("hash_final() of the copy = %s, want md5('hel')", h.ToString())
+	}
+
+	options := types.NewEmptyArray()
+	options.Set(types.NewString("cost"), types.NewInt(4))
+	hash := call("password_hash", types.NewString("secret"), types.NewString("2y"), types.NewArray(options))
+	if !call("password_verify", types.NewString("secret"), hash).ToBool() {
+		t.Errorf("password_verify() of password_hash() failed")
+	}
+}
+
+func TestHashBuiltins_ThrowErrors(t *testing.T) {
+	vm := New()
+
+	ctx, _ := vm.CallCallable(types.NewString("hash_init"), []*types.Value{types.NewString("sha1")})
+	vm.CallCallable(types.NewString("hash_final"), []*types.Value{ctx})
+
+	tests := []struct {
+		name    string
+		args    []*types.Value
+		class   string
+		message string
+	}{
+		{"hash", []*types.Value{types.NewString("nope"), types.NewString("")}, "ValueError", "hash(): Argument #1 ($algo) must be a valid hashing algorithm"},
+		{"hash_update", []*types.Value{ctx, types.NewString("x")}, "TypeError", "hash_update(): Argument #1 ($context) must be a valid, non-finalized HashContext"},
+		{"hash_update", []*types.Value{types.NewString("ctx"), types.NewString("x")}, "TypeError", "hash_update(): Argument #1 ($context) must be of type HashContext, string given"},
+		{"hash_equals", []*types.Value{types.NewInt(1), types.NewString("1")}, "TypeError", "hash_equals(): Argument #1 ($known_string) must be of type string, int given"},
+	}
+
+	for _, tt := range tests {
+		_, err := vm.CallCallable(types.NewString(tt.name), tt.args)
+		throwable, ok := err.(*ThrowableError)
+		if !ok || throwable.Object.ClassEntry.Name != tt.class {
+			t.Errorf("%s(): expected a %s, got %v", tt.name, tt.class, err)
+			continue
+		}
+		if msg := throwableProperty(throwable.Object, "message").ToString(); msg != tt.message {
+			t.Errorf("%s(): expected message %q, got %q", tt.name, tt.message, msg)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package vm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/krizos/php-go/pkg/stdlib/header"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Response Headers
+// ============================================================================
+
+// The headers and status code of the response stay changeable until the
+// first byte of the body reaches the client, or flush() is called: the
+// SAPI layer sends them before the body. Output held in ob_start()
+// buffers does not lock them.
+
+// ResponseHeaders returns the header lines the script set, such as the
+// session cookie, for the SAPI layer to send
+func (vm *VM) ResponseHeaders() []string {
+	return vm.response.Lines()
+}
+
+// ResponseCode returns the status code of the response, 0 if none is set
+func (vm *VM) ResponseCode() int {
+	return vm.response.Code
+}
+
+// SetResponseCode sets the status code of the response; web SAPIs start
+// requests with 200, while the CLI has none
+func (vm *VM) SetResponseCode(code int) {
+	vm.response.Code = code
+}
+
+// outputStart is where the output reaching the client started
+type outputStart struct {
+	file string
+	line int
+}
+
+// headersSent reports whether output has reached the client, after which
+// headers can no longer be sent
+func (vm *VM) headersSent() bool {
+	return vm.outputStart != nil
+}
+
+// sendHeaders locks the headers, recording where the output started
+func (vm *VM) sendHeaders() {
+	if vm.outputStart == nil {
+		vm.outputStart = &outputStart{file: vm.scriptPath, line: vm.currentLine()}
+	}
+}
+
+// emitOutput appends data to the output the client receives; the first
+// byte sends the headers
+func (vm *VM) emitOutput(data []byte) {
+	if len(data) > 0 {
+		vm.sendHeaders()
+	}
+	if vm.outputWriter != nil {
+		// Like PHP, the script carries on if the client went away
+		vm.outputWriter.Write(data)
+		return
+	}
+	vm.output = append(vm.output, data...)
+}
+
+// headersLocked warns and returns true if the headers were sent and can
+// no longer change
+func (vm *VM) headersLocked() bool {
+	if !vm.headersSent() {
+		return false
+	}
+	start := vm.outputStart
+	vm.warning("Cannot modify header information - headers already sent by (output started at %s:%d)", start.file, start.line)
+	return true
+}
+
+// addHeader sets a header line unless the headers were sent, as header()
+// and setcookie() do
+func (vm *VM) addHeader(line string, replace bool, code int) bool {
+	if vm.headersLocked() {
+		return false
+	}
+	vm.response.Add(line, replace, code)
+	return true
+}
+
+// ============================================================================
+// Header Builtins
+// ============================================================================
+
+// registerHeaderBuiltins registers the functions of the response headers
+func (vm *VM) registerHeaderBuiltins() {
+	vm.RegisterBuiltin("header", builtinHeader)
+	vm.RegisterBuiltin("header_remove", builtinHeaderRemove)
+	vm.RegisterBuiltin("headers_list", builtinHeadersList)
+	vm.RegisterBuiltin("headers_sent", builtinHeadersSent)
+	vm.RegisterBuiltin("http_response_code", builtinHttpResponseCode)
+	vm.RegisterBuiltin("setcookie", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return setCookie(vm, "setcookie", args, false)
+	})
+	vm.RegisterBuiltin("setrawcookie", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return setCookie(vm, "setrawcookie", args, true)
+	})
+	vm.RegisterBuiltin("flush", builtinFlush)
+}
+
+// header(string $header, bool $replace = true, int $response_code = 0): void
+func builtinHeader(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("header() expects at least 1 argument, %d given", len(args))
+	}
+	line := args[0].Deref().ToString()
+	replace := len(args) < 2 || args[1].Deref().ToBool()
+	code := 0
+	if len(args) > 2 {
+		code = int(args[2].Deref().ToInt())
+	}
+
+	if warning := header.Check(line); warning != "" {
+		vm.warning("%s", warning)
+		return types.NewNull(), nil
+	}
+	vm.addHeader(line, replace, code)
+	return types.NewNull(), nil
+}
+
+// header_remove(?string $name = null): void
+func builtinHeaderRemove(vm *VM, args []*types.Value) (*types.Value, error) {
+	if vm.headersLocked() {
+		return types.NewNull(), nil
+	}
+	name := ""
+	if len(args) > 0 && !args[0].Deref().IsNull() {
+		name = args[0].Deref().ToString()
+	}
+	vm.response.Remove(name)
+	return types.NewNull(), nil
+}
+
+// headers_list(): array
+func builtinHeadersList(vm *VM, args []*types.Value) (*types.Value, error) {
+	list := types.NewEmptyArray()
+	for _, line := range vm.response.Lines() {
+		list.Append(types.NewString(line))
+	}
+	return types.NewArray(list), nil
+}
+
+// headers_sent(string &$filename = null, int &$line = null): bool
+func builtinHeadersSent(vm *VM, args []*types.Value) (*types.Value, error) {
+	if !vm.headersSent() {
+		assignRefArg(args, 0, types.NewString(""))
+		assignRefArg(args, 1, types.NewInt(0))
+		return types.NewBool(false), nil
+	}
+	assignRefArg(args, 0, types.NewString(vm.outputStart.file))
+	assignRefArg(args, 1, types.NewInt(int64(vm.outputStart.line)))
+	return types.NewBool(true), nil
+}
+
+// http_response_code(int $response_code = 0): int|bool
+func builtinHttpResponseCode(vm *VM, args []*types.Value) (*types.Value, error) {
+	previous := vm.response.Code
+	code := 0
+	if len(args) > 0 {
+		code = int(args[0].Deref().ToInt())
+	}
+	if code == 0 {
+		if previous == 0 {
+			return types.NewBool(false), nil
+		}
+		return types.NewInt(int64(previous)), nil
+	}
+
+	if vm.headersSent() {
+		start := vm.outputStart
+		vm.warning("http_response_code(): Cannot set response code - headers already sent (output started at %s:%d)", start.file, start.line)
+		return types.NewBool(false), nil
+	}
+	vm.response.Code = code
+	if previous == 0 {
+		return types.NewBool(true), nil
+	}
+	return types.NewInt(int64(previous)), nil
+}
+
+// setcookie(string $name, string $value = "", int $expires_or_options = 0, string $path = "",
+// string $domain = "", bool $secure = false, bool $httponly = false): bool
+// setcookie(string $name, string $value = "", array $options = []): bool
+func setCookie(vm *VM, function string, args []*types.Value, raw bool) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("%s() expects at least 1 argument, %d given", function, len(args))
+	}
+	args = derefArgs(args)
+	cookie := &header.Cookie{Name: args[0].ToString(), Raw: raw}
+	if len(args) > 1 {
+		cookie.Value = args[1].ToString()
+	}
+
+	options := len(args) > 2 && args[2].IsArray()
+	if options {
+		if len(args) > 3 {
+			return nil, vm.ThrowError("ArgumentCountError", "%s(): Expects exactly 3 arguments when argument #3 ($expires_or_options) is an array", function)
+		}
+		if err := cookieOptions(cookie, function, args[2].ToArray()); err != nil {
+			return nil, vm.headerError(err)
+		}
+	} else {
+		if len(args) > 2 {
+			cookie.Expires = args[2].ToInt()
+		}
+		if len(args) > 3 {
+			cookie.Path = args[3].ToString()
+		}
+		if len(args) > 4 {
+			cookie.Domain = args[4].ToString()
+		}
+		if len(args) > 5 {
+			cookie.Secure = args[5].ToBool()
+		}
+		if len(args) > 6 {
+			cookie.HTTPOnly = args[6].ToBool()
+		}
+	}
+
+	if err := cookie.Validate(function, options); err != nil {
+		return nil, vm.headerError(err)
+	}
+	return types.NewBool(vm.addHeader(cookie.Line(time.Now()), false, 0)), nil
+}
+
+// cookieOptions reads the attributes of a cookie from the options array
+// of setcookie()
+func cookieOptions(cookie *header.Cookie, function string, options *types.Array) error {
+	var err error
+	options.Each(func(key, value *types.Value) bool {
+		if !key.IsString() {
+			err = &header.Error{Class: "ValueError", Message: function + "(): option array cannot have numeric keys"}
+			return false
+		}
+		switch name := key.ToString(); name {
+		case "expires":
+			cookie.Expires = value.Deref().ToInt()
+		case "path":
+			cookie.Path = value.Deref().ToString()
+		case "domain":
+			cookie.Domain = value.Deref().ToString()
+		case "secure":
+			cookie.Secure = value.Deref().ToBool()
+		case "httponly":
+			cookie.HTTPOnly = value.Deref().ToBool()
+		case "samesite":
+			cookie.SameSite = value.Deref().ToString()
+		default:
+			err = &header.Error{Class: "ValueError", Message: fmt.Sprintf("%s(): option \"%s\" is invalid", function, name)}
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// headerError converts header failures into PHP exceptions
+func (vm *VM) headerError(err error) error {
+	if e, ok := err.(*header.Error); ok {
+		return vm.ThrowError(e.Class, "%s", e.Message)
+	}
+	return err
+}
+
+// flush(): void
+func builtinFlush(vm *VM, args []*types.Value) (*types.Value, error) {
+	vm.sendHeaders()
+	return types.NewNull(), nil
+}
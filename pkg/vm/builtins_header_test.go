@@ -0,0 +1,114 @@
+package vm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestHeaderBuiltins(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+
+	if result := call("http_response_code"); result.IsBool() && result.ToBool() || !result.IsBool() {
+		t.Errorf("http_response_code() without a code should be false, got %v", result)
+	}
+	vm.SetResponseCode(200)
+
+	call("header", types.NewString("Content-Type: text/plain"))
+	call("header", types.NewString("X-Tag: a"))
+	call("header", types.NewString("X-Tag: b"), types.NewBool(false))
+	call("header", types.NewString("Location: /next"))
+	if vm.ResponseCode() != 302 {
+		t.Errorf("Location should redirect with 302, got %d", vm.ResponseCode())
+	}
+	if previous := call("http_response_code", types.NewInt(303)); previous.ToInt() != 302 {
+		t.Errorf("http_response_code() should return the previous code, got %v", previous)
+	}
+	call("header_remove", types.NewString("x-tag"))
+	call("setcookie", types.NewString("name"), types.NewString("a b"))
+
+	want := []string{"Content-Type: text/plain", "Location: /next", "Set-Cookie: name=a%20b"}
+	if !reflect.DeepEqual(vm.ResponseHeaders(), want) {
+		t.Errorf("Expected headers %q, got %q", want, vm.ResponseHeaders())
+	}
+	list := call("headers_list").ToArray()
+	if list.Len() != len(want) {
+		t.Errorf("headers_list() should list %d headers, got %d", len(want), list.Len())
+	}
+
+	call("header", types.NewString("X-Injected: 1\r\nX-Other: 2"))
+	if !strings.Contains(vm.GetOutput(), "Header may not contain more than a single header") {
+		t.Errorf("Expected a warning about the new line, got %q", vm.GetOutput())
+	}
+}
+
+func TestHeaderBuiltins_LockedByOutput(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+	vm.SetScriptPath("/app/index.php")
+
+	// Buffered output does not send the headers
+	call("ob_start")
+	vm.writeOutput([]byte("buffered"))
+	if call("headers_sent").ToBool() {
+		t.Fatal("Output in a buffer should not send the headers")
+	}
+	call("header", types.NewString("X-Early: 1"))
+	call("ob_end_flush")
+
+	file, line := types.NewReference(types.NewNull()), types.NewReference(types.NewNull())
+	if !call("headers_sent", file, line).ToBool() {
+		t.Fatal("Flushed output should send the headers")
+	}
+	if file.Deref().ToString() != "/app/index.php" {
+		t.Errorf("headers_sent() should report where the output started, got %q", file.Deref().ToString())
+	}
+
+	vm.ClearOutput()
+	call("header", types.NewString("X-Late: 1"))
+	if got := vm.GetOutput(); !strings.Contains(got, "Cannot modify header information - headers already sent by (output started at /app/index.php:0)") {
+		t.Errorf("Expected a warning, got %q", got)
+	}
+	if result := call("setcookie", types.NewString("late"), types.NewString("1")); result.ToBool() {
+		t.Error("setcookie() after the output should fail")
+	}
+	if want := []string{"X-Early: 1"}; !reflect.DeepEqual(vm.ResponseHeaders(), want) {
+		t.Errorf("Expected headers %q, got %q", want, vm.ResponseHeaders())
+	}
+}
+
+func TestHeaderBuiltins_Flush(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+	call("flush")
+	if !vm.headersSent() {
+		t.Error("flush() should send the headers")
+	}
+}
+
+func TestSetcookie_Options(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+
+	options := types.NewEmptyArray()
+	options.Set(types.NewString("path"), types.NewString("/"))
+	options.Set(types.NewString("httponly"), types.NewBool(true))
+	options.Set(types.NewString("samesite"), types.NewString("Strict"))
+	call("setrawcookie", types.NewString("token"), types.NewString("x%20y"), types.NewArray(options))
+	if want := []string{"Set-Cookie: token=x%20y; path=/; HttpOnly; SameSite=Strict"}; !reflect.DeepEqual(vm.ResponseHeaders(), want) {
+		t.Errorf("Expected %q, got %q", want, vm.ResponseHeaders())
+	}
+
+	options.Set(types.NewString("colour"), types.NewString("red"))
+	_, err := vm.CallCallable(types.NewString("setcookie"), []*types.Value{types.NewString("a"), types.NewString("b"), types.NewArray(options)})
+	if err == nil || !strings.Contains(err.Error(), `setcookie(): option "colour" is invalid`) {
+		t.Errorf("Expected a ValueError for the unknown option, got %v", err)
+	}
+	_, err = vm.CallCallable(types.NewString("setcookie"), []*types.Value{types.NewString("")})
+	if err == nil || !strings.Contains(err.Error(), "cannot be empty") {
+		t.Errorf("Expected a ValueError for the empty name, got %v", err)
+	}
+}
@@ -0,0 +1,299 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/stdlib/datetime"
+	"github.com/krizos/php-go/pkg/stdlib/filter"
+	"github.com/krizos/php-go/pkg/stdlib/session"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Configuration
+// ============================================================================
+
+// Config returns the ini configuration, for the embedder to load a
+// configuration file and apply -d flags before running a script
+func (vm *VM) Config() *runtime.Config {
+	return vm.config
+}
+
+// sessionDirectives are the session.* directives applied to the session
+var sessionDirectives = []string{
+	"name", "save_path", "cookie_lifetime", "cookie_path", "cookie_domain", "cookie_secure",
+	"cookie_httponly", "cookie_samesite", "use_cookies", "lazy_write", "gc_maxlifetime",
+	"gc_probability", "gc_divisor",
+}
+
+// bindConfig makes the directives the VM enforces apply their values
+func (vm *VM) bindConfig() {
+	c := vm.config
+	c.OnChange("error_reporting", func(value string) error {
+		level, _ := strconv.Atoi(strings.TrimSpace(value))
+		vm.errorReporting = runtime.ErrorType(level)
+		return nil
+	})
+	c.OnChange("display_errors", func(value string) error {
+		// "stderr" and "stdout" choose the stream in PHP
+		switch strings.ToLower(value) {
+		case "stderr", "stdout":
+			vm.displayErrors = true
+		default:
+			vm.displayErrors = runtime.IniBool(value)
+		}
+		return nil
+	})
+	c.OnChange("include_path", func(value string) error {
+		vm.includePath = filepath.SplitList(value)
+		return nil
+	})
+	c.OnChange("max_execution_time", func(value string) error {
+		seconds, _ := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		vm.setTimeLimit(seconds)
+		return nil
+	})
+	c.OnChange("memory_limit", vm.setMemoryLimit)
+	c.OnChange("precision", func(value string) error {
+		p, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || p < -1 {
+			return fmt.Errorf("precision must be an integer greater than or equal to -1")
+		}
+		types.SetPrecision(p)
+		return nil
+	})
+	c.OnChange("serialize_precision", func(value string) error {
+		p, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || p < -1 {
+			return fmt.Errorf("serialize_precision must be an integer greater than or equal to -1")
+		}
+		types.SetSerializePrecision(p)
+		return nil
+	})
+	c.OnChange("xdebug.max_nesting_level", func(value string) error {
+		depth, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || depth < 1 {
+			return fmt.Errorf("xdebug.max_nesting_level must be a positive integer")
+		}
+		vm.maxStackDepth = depth
+		return nil
+	})
+	c.OnChange("zend.enable_gc", func(value string) error {
+		vm.gc.enabled = runtime.IniBool(value)
+		return nil
+	})
+	c.OnChange("date.timezone", func(value string) error {
+		if value == "" {
+			return nil
+		}
+		loc, ok := datetime.LoadTimezone(value)
+		if !ok {
+			return fmt.Errorf("Invalid date.timezone value '%s'", value)
+		}
+		datetime.SetDefaultTimezone(loc)
+		return nil
+	})
+	for _, option := range sessionDirectives {
+		c.OnChange("session."+option, func(value string) error {
+			if vm.session == nil {
+				return nil
+			}
+			if vm.session.Status == session.PHP_SESSION_ACTIVE {
+				return fmt.Errorf("Session ini settings cannot be changed when a session is active")
+			}
+			vm.session.SetOption(option, types.NewString(value))
+			return nil
+		})
+	}
+}
+
+// applySessionConfig sets the session.* directives on a new session
+func (vm *VM) applySessionConfig(s *session.Session) {
+	for _, option := range sessionDirectives {
+		if value, ok := vm.config.Get("session." + option); ok {
+			s.SetOption(option, types.NewString(value))
+		}
+	}
+}
+
+// startRequest prepares the VM for a script: $_ENV is filled when
+// variables_order includes "E" and the SAPI did not set it, and the
+// execution timer starts
+func (vm *VM) startRequest() {
+	order, _ := vm.config.Get("variables_order")
+	if strings.ContainsAny(order, "Ee") && vm.requestInput[filter.INPUT_ENV] == nil {
+		vm.SetRequestInput(filter.INPUT_ENV, environment())
+	}
+	vm.setTimeLimit(int64(vm.timeLimit / 1e9))
+}
+
+// environment returns the environment variables of the process
+func environment() *types.Array {
+	env := types.NewEmptyArray()
+	for _, entry := range os.Environ() {
+		if name, value, ok := strings.Cut(entry, "="); ok && name != "" {
+			env.Set(types.NewString(name), types.NewString(value))
+		}
+	}
+	return env
+}
+
+// iniString converts the value of ini_set() to the directive's string
+func iniString(value *types.Value) string {
+	if value.IsBool() {
+		if value.ToBool() {
+			return "1"
+		}
+		return ""
+	}
+	return value.ToString()
+}
+
+// ============================================================================
+// ini Builtins
+// ============================================================================
+
+// registerIniBuiltins registers the configuration and environment
+// functions
+func (vm *VM) registerIniBuiltins() {
+	vm.RegisterBuiltin("ini_get", builtinIniGet)
+	vm.RegisterBuiltin("ini_set", builtinIniSet("ini_set"))
+	vm.RegisterBuiltin("ini_alter", builtinIniSet("ini_alter"))
+	vm.RegisterBuiltin("ini_restore", builtinIniRestore)
+	vm.RegisterBuiltin("ini_get_all", builtinIniGetAll)
+	vm.RegisterBuiltin("get_cfg_var", builtinGetCfgVar)
+	vm.RegisterBuiltin("php_ini_loaded_file", builtinPhpIniLoadedFile)
+	vm.RegisterBuiltin("getenv", builtinGetenv)
+	vm.RegisterBuiltin("putenv", builtinPutenv)
+}
+
+// ini_get(string $option): string|false
+func builtinIniGet(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("ini_get() expects exactly 1 argument, 0 given")
+	}
+	value, ok := vm.config.Get(args[0].Deref().ToString())
+	if !ok {
+		return types.NewBool(false), nil
+	}
+	return types.NewString(value), nil
+}
+
+// ini_set(string $option, string|int|float|bool|null $value): string|false
+// Unknown directives and those scripts cannot change give false; a
+// rejected value warns.
+func builtinIniSet(name string) BuiltinFunction {
+	return func(vm *VM, args []*types.Value) (*types.Value, error) {
+		if len(args) < 2 {
+			return nil, fmt.Errorf("%s() expects exactly 2 arguments, %d given", name, len(args))
+		}
+		old, err := vm.config.Set(args[0].Deref().ToString(), iniString(args[1].Deref()), runtime.INI_USER)
+		if err != nil {
+			if !errors.Is(err, runtime.ErrUnknownDirective) && !errors.Is(err, runtime.ErrNotChangeable) {
+				vm.warning("%s(): %s", name, err)
+			}
+			return types.NewBool(false), nil
+		}
+		return types.NewString(old), nil
+	}
+}
+
+// ini_restore(string $option): void
+func builtinIniRestore(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("ini_restore() expects exactly 1 argument, 0 given")
+	}
+	vm.config.Restore(args[0].Deref().ToString())
+	return types.NewNull(), nil
+}
+
+// ini_get_all(?string $extension = null, bool $details = true): array|false
+// The extension selects the directives with its prefix, such as
+// "session".
+func builtinIniGetAll(vm *VM, args []*types.Value) (*types.Value, error) {
+	args = derefArgs(args)
+	prefix := ""
+	if len(args) > 0 && !args[0].IsNull() {
+		prefix = args[0].ToString() + "."
+	}
+	details := len(args) < 2 || args[1].ToBool()
+
+	result := types.NewEmptyArray()
+	for _, setting := range vm.config.All() {
+		if !strings.HasPrefix(setting.Name, prefix) {
+			continue
+		}
+		if !details {
+			result.Set(types.NewString(setting.Name), types.NewString(setting.LocalValue))
+			continue
+		}
+		entry := types.NewEmptyArray()
+		entry.Set(types.NewString("global_value"), types.NewString(setting.GlobalValue))
+		entry.Set(types.NewString("local_value"), types.NewString(setting.LocalValue))
+		entry.Set(types.NewString("access"), types.NewInt(int64(setting.Access)))
+		result.Set(types.NewString(setting.Name), types.NewArray(entry))
+	}
+	if prefix != "" && result.Len() == 0 {
+		vm.warning("ini_get_all(): Extension \"%s\" cannot be found", args[0].ToString())
+		return types.NewBool(false), nil
+	}
+	return types.NewArray(result), nil
+}
+
+// get_cfg_var(string $option): string|array|false
+func builtinGetCfgVar(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("get_cfg_var() expects exactly 1 argument, 0 given")
+	}
+	value, ok := vm.config.GlobalValue(args[0].Deref().ToString())
+	if !ok {
+		return types.NewBool(false), nil
+	}
+	return types.NewString(value), nil
+}
+
+// php_ini_loaded_file(): string|false
+func builtinPhpIniLoadedFile(vm *VM, args []*types.Value) (*types.Value, error) {
+	if path := vm.config.LoadedFile(); path != "" {
+		return types.NewString(path), nil
+	}
+	return types.NewBool(false), nil
+}
+
+// getenv(?string $name = null, bool $local_only = false): array|string|false
+func builtinGetenv(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) == 0 || args[0].Deref().IsNull() {
+		return types.NewArray(environment()), nil
+	}
+	value, ok := os.LookupEnv(args[0].Deref().ToString())
+	if !ok {
+		return types.NewBool(false), nil
+	}
+	return types.NewString(value), nil
+}
+
+// putenv(string $assignment): bool
+// "NAME=value" sets a variable of the process and "NAME" removes it.
+func builtinPutenv(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("putenv() expects exactly 1 argument, 0 given")
+	}
+	assignment := args[0].Deref().ToString()
+	name, value, set := strings.Cut(assignment, "=")
+	if name == "" {
+		return nil, vm.ThrowError("ValueError", "putenv(): Argument #1 ($assignment) must have a valid syntax")
+	}
+	var err error
+	if set {
+		err = os.Setenv(name, value)
+	} else {
+		err = os.Unsetenv(name)
+	}
+	return types.NewBool(err == nil), nil
+}
@@ -0,0 +1,166 @@
+package vm
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/stdlib/filter"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestIniBuiltins_GetSetRestore(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+
+	if old := call("ini_set", types.NewString("error_reporting"), types.NewInt(int64(runtime.E_WARNING))); old.ToString() != "32767" {
+		t.Errorf("ini_set() = %v", old)
+	}
+	if vm.ErrorReporting() != runtime.E_WARNING {
+		t.Errorf("error_reporting level = %d", vm.ErrorReporting())
+	}
+	if level := call("error_reporting", types.NewInt(int64(runtime.E_ALL))); level.ToInt() != int64(runtime.E_WARNING) {
+		t.Errorf("error_reporting() = %v", level)
+	}
+	if value := call("ini_get", types.NewString("error_reporting")); value.ToString() != "32767" {
+		t.Errorf("ini_get() after error_reporting() = %v", value)
+	}
+
+	call("ini_set", types.NewString("include_path"), types.NewString("/a:/b"))
+	if paths := vm.IncludePath(); len(paths) != 2 || paths[1] != "/b" {
+		t.Errorf("include path = %v", paths)
+	}
+	call("ini_restore", types.NewString("include_path"))
+	if value := call("get_include_path"); value.ToString() != "." {
+		t.Errorf("get_include_path() after ini_restore() = %v", value)
+	}
+
+	if result := call("ini_get", types.NewString("no.such")); !result.IsBool() || result.ToBool() {
+		t.Errorf("ini_get(unknown) = %v", result)
+	}
+	if result := call("ini_set", types.NewString("variables_order"), types.NewString("GP")); !result.IsBool() || result.ToBool() {
+		t.Errorf("ini_set(INI_PERDIR) = %v", result)
+	}
+	if result := call("ini_set", types.NewString("date.timezone"), types.NewString("Mars/Olympus")); result.ToBool() {
+		t.Errorf("ini_set(invalid timezone) = %v", result)
+	}
+	if !strings.Contains(vm.GetOutput(), "Invalid date.timezone value 'Mars/Olympus'") {
+		t.Errorf("output = %q", vm.GetOutput())
+	}
+
+	all := call("ini_get_all", types.NewString("session"), types.NewBool(false)).ToArray()
+	if name, _ := all.Get(types.NewString("session.name")); name.ToString() != "PHPSESSID" {
+		t.Errorf("ini_get_all() = %v", all)
+	}
+}
+
+func TestIniBuiltins_Precision(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+	defer types.SetPrecision(types.DefaultPrecision)
+	defer types.SetSerializePrecision(types.DefaultSerializePrecision)
+
+	call("ini_set", types.NewString("precision"), types.NewInt(3))
+	if s := types.NewFloat(3.14159).ToString(); s != "3.14" {
+		t.Errorf("(string) 3.14159 with precision 3 = %q", s)
+	}
+	call("ini_set", types.NewString("serialize_precision"), types.NewInt(5))
+	if s := call("var_export", types.NewFloat(3.14159), types.NewBool(true)).ToString(); s != "3.1416" {
+		t.Errorf("var_export(3.14159) with serialize_precision 5 = %q", s)
+	}
+	if result := call("ini_set", types.NewString("precision"), types.NewString("high")); result.ToBool() {
+		t.Errorf("ini_set(invalid precision) = %v", result)
+	}
+}
+
+func TestIniBuiltins_ConfigFile(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+	if _, err := vm.Config().Set("session.name", "APPSESS", runtime.INI_SYSTEM); err != nil {
+		t.Fatal(err)
+	}
+	vm.Config().Set("custom.flag", "on", runtime.INI_SYSTEM)
+
+	if name := call("session_name"); name.ToString() != "APPSESS" {
+		t.Errorf("session_name() = %v", name)
+	}
+	if value := call("get_cfg_var", types.NewString("custom.flag")); value.ToString() != "on" {
+		t.Errorf("get_cfg_var() = %v", value)
+	}
+	if file := call("php_ini_loaded_file"); !file.IsBool() {
+		t.Errorf("php_ini_loaded_file() = %v", file)
+	}
+}
+
+func TestIniBuiltins_Environment(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+	t.Setenv("PHPGO_ENV_TEST", "")
+
+	call("putenv", types.NewString("PHPGO_ENV_TEST=value"))
+	if value := call("getenv", types.NewString("PHPGO_ENV_TEST")); value.ToString() != "value" {
+		t.Errorf("getenv() = %v", value)
+	}
+	if env := call("getenv").ToArray(); !env.HasKey(types.NewString("PHPGO_ENV_TEST")) {
+		t.Error("getenv() misses the variable")
+	}
+	call("putenv", types.NewString("PHPGO_ENV_TEST"))
+	if _, ok := os.LookupEnv("PHPGO_ENV_TEST"); ok {
+		t.Error("putenv() did not remove the variable")
+	}
+
+	_, err := vm.CallCallable(types.NewString("putenv"), []*types.Value{types.NewString("=value")})
+	expectThrown(t, err, "ValueError", "putenv(): Argument #1 ($assignment) must have a valid syntax")
+
+	os.Setenv("PHPGO_ENV_TEST", "request")
+	vm.startRequest()
+	if env := vm.requestInput[filter.INPUT_ENV]; env == nil || !env.HasKey(types.NewString("PHPGO_ENV_TEST")) {
+		t.Errorf("$_ENV = %v", env)
+	}
+}
+
+func TestLimits_MaxExecutionTime(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{}
+	vm.setTimeLimit(1)
+	vm.deadline = time.Now()
+
+	// while (true) {}
+	err := runMain(vm, &CompiledFunction{Name: "main", Instructions: Instructions{
+		{Opcode: OpJmp, Op1: Operand{Value: 0}},
+	}})
+	var fatal *FatalError
+	if !errors.As(err, &fatal) || fatal.Message != "Maximum execution time of 1 second exceeded" {
+		t.Fatalf("error = %v", err)
+	}
+	if !vm.deadline.IsZero() {
+		t.Error("the time limit was not lifted")
+	}
+}
+
+func TestLimits_MemoryLimit(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+
+	if result := call("ini_set", types.NewString("memory_limit"), types.NewString("1K")); result.ToBool() {
+		t.Errorf("ini_set(memory_limit below usage) = %v", result)
+	}
+	if !strings.Contains(vm.GetOutput(), "Failed to set memory limit to 1024 bytes") {
+		t.Errorf("output = %q", vm.GetOutput())
+	}
+	if result := call("ini_set", types.NewString("memory_limit"), types.NewString("-1")); result.ToString() != "128M" {
+		t.Errorf("ini_set(memory_limit) = %v", result)
+	}
+	if usage := call("memory_get_usage"); usage.ToInt() <= 0 {
+		t.Errorf("memory_get_usage() = %v", usage)
+	}
+	if peak := call("memory_get_peak_usage"); peak.ToInt() <= 0 {
+		t.Errorf("memory_get_peak_usage() = %v", peak)
+	}
+	if ok := call("set_time_limit", types.NewInt(30)); !ok.ToBool() || vm.deadline.IsZero() {
+		t.Errorf("set_time_limit() = %v", ok)
+	}
+}
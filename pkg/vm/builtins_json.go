@@ -0,0 +1,114 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+
+	stdjson "github.com/krizos/php-go/pkg/stdlib/json"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// JSON Builtins
+// ============================================================================
+
+// registerJsonBuiltins registers json_encode/json_decode and the
+// JsonSerializable interface
+func (vm *VM) registerJsonBuiltins() {
+	serializable := types.NewInterfaceEntry("JsonSerializable")
+	serializable.Methods["jsonSerialize"] = &types.MethodDef{
+		Name: "jsonSerialize", Visibility: types.VisibilityPublic, IsAbstract: true,
+	}
+	vm.classes[serializable.Name] = interfaceClass(serializable)
+
+	vm.RegisterBuiltin("json_encode", builtinJsonEncode)
+	vm.RegisterBuiltin("json_decode", builtinJsonDecode)
+	vm.RegisterBuiltin("json_last_error", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return stdjson.JsonLastError(), nil
+	})
+	vm.RegisterBuiltin("json_last_error_msg", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return stdjson.JsonLastErrorMsg(), nil
+	})
+}
+
+// jsonSerialize encodes objects implementing JsonSerializable as the
+// result of their jsonSerialize() method
+func (vm *VM) jsonSerialize(obj *types.Object) (*types.Value, bool, error) {
+	if !vm.isInstanceOf(obj.ClassEntry, "JsonSerializable") {
+		return nil, false, nil
+	}
+	method, ok := obj.ClassEntry.GetMethod("jsonSerialize")
+	if !ok {
+		return nil, false, nil
+	}
+	result, err := vm.invokeTarget(vm.methodTarget(obj.ClassEntry, obj, method), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	return result, true, nil
+}
+
+// jsonException converts a JSON error into a JsonException carrying the
+// JSON_ERROR_* code
+func (vm *VM) jsonException(err error) error {
+	var jsonErr *stdjson.Error
+	if !errors.As(err, &jsonErr) {
+		return err
+	}
+	exception := vm.ThrowError("JsonException", "%s", jsonErr.Error())
+	setThrowableProperty(exception.(*ThrowableError).Object, "code", types.NewInt(int64(jsonErr.Code)))
+	return exception
+}
+
+// json_encode(mixed $value, int $flags = 0, int $depth = 512): string|false
+func builtinJsonEncode(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("json_encode() expects at least 1 argument, 0 given")
+	}
+	options, depth := 0, stdjson.DefaultDepth
+	if len(args) > 1 {
+		options = int(args[1].Deref().ToInt())
+	}
+	if len(args) > 2 {
+		depth = int(args[2].Deref().ToInt())
+	}
+	if depth <= 0 {
+		return nil, vm.ThrowError("ValueError", "json_encode(): Argument #3 ($depth) must be greater than 0")
+	}
+
+	result, err := stdjson.Encode(args[0], options, depth, vm.jsonSerialize)
+	if err != nil {
+		return nil, vm.jsonException(err)
+	}
+	return result, nil
+}
+
+// json_decode(string $json, ?bool $associative = null, int $depth = 512, int $flags = 0): mixed
+func builtinJsonDecode(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("json_decode() expects at least 1 argument, 0 given")
+	}
+	associative, depth, flags := false, stdjson.DefaultDepth, 0
+	if len(args) > 1 {
+		associative = args[1].Deref().ToBool()
+	}
+	if len(args) > 2 {
+		depth = int(args[2].Deref().ToInt())
+	}
+	if len(args) > 3 {
+		flags = int(args[3].Deref().ToInt())
+	}
+	if depth <= 0 {
+		return nil, vm.ThrowError("ValueError", "json_decode(): Argument #3 ($depth) must be greater than 0")
+	}
+	// A null $associative leaves the choice to JSON_OBJECT_AS_ARRAY
+	if len(args) > 1 && !args[1].Deref().IsNull() && !associative {
+		flags &^= stdjson.JSON_OBJECT_AS_ARRAY
+	}
+
+	result, err := stdjson.Decode(args[0].Deref().ToString(), associative, depth, flags)
+	if err != nil {
+		return nil, vm.jsonException(err)
+	}
+	return result, nil
+}
@@ -0,0 +1,46 @@
+package vm
+
+import (
+	"testing"
+
+	stdjson "github.com/krizos/php-go/pkg/stdlib/json"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestJsonEncode_UsesJsonSerializable(t *testing.T) {
+	vm := New()
+
+	class := types.NewClassEntry("Point")
+	class.Interfaces = append(class.Interfaces, types.NewInterfaceEntry("JsonSerializable"))
+	addNativeMethod(class, "jsonSerialize", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return types.NewArray(types.NewArrayFromSlice([]*types.Value{types.NewInt(1), types.NewInt(2)})), nil
+	})
+	vm.classes[class.Name] = class
+
+	point := types.NewObject(types.NewObjectFromClass(class))
+	result, err := vm.CallCallable(types.NewString("json_encode"), []*types.Value{point})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ToString() != "[1,2]" {
+		t.Errorf("Expected jsonSerialize() to be encoded, got %v", result)
+	}
+}
+
+func TestJsonDecode_ThrowsJsonException(t *testing.T) {
+	vm := New()
+
+	_, err := vm.CallCallable(types.NewString("json_decode"), []*types.Value{
+		types.NewString("{"), types.NewBool(true), types.NewInt(512), types.NewInt(stdjson.JSON_THROW_ON_ERROR),
+	})
+	throwable, ok := err.(*ThrowableError)
+	if !ok || throwable.Object.ClassEntry.Name != "JsonException" {
+		t.Fatalf("Expected a JsonException, got %v", err)
+	}
+	if code := throwableProperty(throwable.Object, "code").ToInt(); code != stdjson.JSON_ERROR_SYNTAX {
+		t.Errorf("Expected code JSON_ERROR_SYNTAX, got %d", code)
+	}
+	if msg := throwableProperty(throwable.Object, "message").ToString(); msg != "Syntax error" {
+		t.Errorf("Expected message 'Syntax error', got %q", msg)
+	}
+}
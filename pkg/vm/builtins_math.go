@@ -0,0 +1,110 @@
+package vm
+
+import (
+	"fmt"
+
+	stdmath "github.com/krizos/php-go/pkg/stdlib/math"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Math Builtins
+// ============================================================================
+
+// mathFunction adapts a pkg/stdlib/math function to a builtin, checking
+// the required argument count and dereferencing the arguments
+type mathFunction struct {
+	required int
+	call     func(args []*types.Value) (*types.Value, error)
+}
+
+// total adapts a math function that cannot fail
+func total(fn func(a []*types.Value) *types.Value) func(a []*types.Value) (*types.Value, error) {
+	return func(a []*types.Value) (*types.Value, error) {
+		return fn(a), nil
+	}
+}
+
+// mathFunctions maps the math functions to their pkg/stdlib/math
+// implementations
+var mathFunctions = map[string]mathFunction{
+	"abs":   {1, total(func(a []*types.Value) *types.Value { return stdmath.Abs(a[0]) })},
+	"ceil":  {1, total(func(a []*types.Value) *types.Value { return stdmath.Ceil(a[0]) })},
+	"floor": {1, total(func(a []*types.Value) *types.Value { return stdmath.Floor(a[0]) })},
+	"round": {1, func(a []*types.Value) (*types.Value, error) { return stdmath.Round(a[0], a[1:]...) }},
+	"min":   {0, func(a []*types.Value) (*types.Value, error) { return stdmath.Min(a...) }},
+	"max":   {0, func(a []*types.Value) (*types.Value, error) { return stdmath.Max(a...) }},
+	"pow":   {2, total(func(a []*types.Value) *types.Value { return stdmath.Pow(a[0], a[1]) })},
+	"sqrt":  {1, total(func(a []*types.Value) *types.Value { return stdmath.Sqrt(a[0]) })},
+	"fmod":  {2, total(func(a []*types.Value) *types.Value { return stdmath.Fmod(a[0], a[1]) })},
+	"fdiv":  {2, total(func(a []*types.Value) *types.Value { return stdmath.Fdiv(a[0], a[1]) })},
+	"intdiv": {2, func(a []*types.Value) (*types.Value, error) {
+		return stdmath.Intdiv(a[0], a[1])
+	}},
+	"hypot": {2, total(func(a []*types.Value) *types.Value { return stdmath.Hypot(a[0], a[1]) })},
+	"pi":    {0, total(func(a []*types.Value) *types.Value { return stdmath.Pi() })},
+
+	"sin":     {1, total(func(a []*types.Value) *types.Value { return stdmath.Sin(a[0]) })},
+	"cos":     {1, total(func(a []*types.Value) *types.Value { return stdmath.Cos(a[0]) })},
+	"tan":     {1, total(func(a []*types.Value) *types.Value { return stdmath.Tan(a[0]) })},
+	"asin":    {1, total(func(a []*types.Value) *types.Value { return stdmath.Asin(a[0]) })},
+	"acos":    {1, total(func(a []*types.Value) *types.Value { return stdmath.Acos(a[0]) })},
+	"atan":    {1, total(func(a []*types.Value) *types.Value { return stdmath.Atan(a[0]) })},
+	"atan2":   {2, total(func(a []*types.Value) *types.Value { return stdmath.Atan2(a[0], a[1]) })},
+	"deg2rad": {1, total(func(a []*types.Value) *types.Value { return stdmath.Deg2rad(a[0]) })},
+	"rad2deg": {1, total(func(a []*types.Value) *types.Value { return stdmath.Rad2deg(a[0]) })},
+
+	"exp":   {1, total(func(a []*types.Value) *types.Value { return stdmath.Exp(a[0]) })},
+	"log":   {1, func(a []*types.Value) (*types.Value, error) { return stdmath.Log(a[0], a[1:]...) }},
+	"log10": {1, total(func(a []*types.Value) *types.Value { return stdmath.Log10(a[0]) })},
+	"log1p": {1, total(func(a []*types.Value) *types.Value { return stdmath.Log1p(a[0]) })},
+	"expm1": {1, total(func(a []*types.Value) *types.Value { return stdmath.Expm1(a[0]) })},
+
+	"is_nan":      {1, total(func(a []*types.Value) *types.Value { return stdmath.IsNan(a[0]) })},
+	"is_finite":   {1, total(func(a []*types.Value) *types.Value { return stdmath.IsFinite(a[0]) })},
+	"is_infinite": {1, total(func(a []*types.Value) *types.Value { return stdmath.IsInfinite(a[0]) })},
+
+	"number_format": {1, total(func(a []*types.Value) *types.Value { return stdmath.NumberFormat(a[0], a[1:]...) })},
+
+	"base_convert": {3, func(a []*types.Value) (*types.Value, error) {
+		return stdmath.BaseConvert(a[0], a[1], a[2])
+	}},
+	"bindec": {1, total(func(a []*types.Value) *types.Value { return stdmath.Bindec(a[0]) })},
+	"octdec": {1, total(func(a []*types.Value) *types.Value { return stdmath.Octdec(a[0]) })},
+	"hexdec": {1, total(func(a []*types.Value) *types.Value { return stdmath.Hexdec(a[0]) })},
+	"decbin": {1, total(func(a []*types.Value) *types.Value { return stdmath.Decbin(a[0]) })},
+	"decoct": {1, total(func(a []*types.Value) *types.Value { return stdmath.Decoct(a[0]) })},
+	"dechex": {1, total(func(a []*types.Value) *types.Value { return stdmath.Dechex(a[0]) })},
+
+	"rand":          {0, total(func(a []*types.Value) *types.Value { return stdmath.Rand(a...) })},
+	"mt_rand":       {0, func(a []*types.Value) (*types.Value, error) { return stdmath.MtRand(a...) }},
+	"srand":         {0, total(func(a []*types.Value) *types.Value { return stdmath.MtSrand(a...) })},
+	"mt_srand":      {0, total(func(a []*types.Value) *types.Value { return stdmath.MtSrand(a...) })},
+	"getrandmax":    {0, total(func(a []*types.Value) *types.Value { return stdmath.GetRandMax() })},
+	"mt_getrandmax": {0, total(func(a []*types.Value) *types.Value { return stdmath.MtGetRandMax() })},
+	"random_int": {2, func(a []*types.Value) (*types.Value, error) {
+		return stdmath.RandomInt(a[0], a[1])
+	}},
+}
+
+// registerMathBuiltins registers the math functions. Errors of the math
+// package are thrown as the exception class they name.
+func (vm *VM) registerMathBuiltins() {
+	for name, fn := range mathFunctions {
+		vm.RegisterBuiltin(name, fn.builtin(name))
+	}
+}
+
+// builtin wraps the function with an argument count check
+func (fn mathFunction) builtin(name string) BuiltinFunction {
+	return func(vm *VM, args []*types.Value) (*types.Value, error) {
+		if len(args) < fn.required {
+			return nil, fmt.Errorf("%s() expects at least %d argument(s), %d given", name, fn.required, len(args))
+		}
+		result, err := fn.call(derefArgs(args))
+		if e, ok := err.(*stdmath.Error); ok {
+			return nil, vm.ThrowError(e.Class, "%s", e.Message)
+		}
+		return result, err
+	}
+}
@@ -0,0 +1,65 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestMathBuiltins(t *testing.T) {
+	vm := New()
+
+	call := func(name string, args ...*types.Value) *types.Value {
+		t.Helper()
+		result, err := vm.CallCallable(types.NewString(name), args)
+		if err != nil {
+			t.Fatalf("%s() failed: %v", name, err)
+		}
+		return result
+	}
+
+	if r := call("round", types.NewFloat(2.5), types.NewInt(0), types.NewInt(3)); r.ToFloat() != 2 {
+		t.Errorf("round(2.5, 0, PHP_ROUND_HALF_EVEN) = %v, want 2", r.ToFloat())
+	}
+	if r := call("max", types.NewString("apple"), types.NewInt(10)); r.ToString() != "apple" {
+		t.Errorf("max('apple', 10) = %v, want 'apple'", r)
+	}
+	if r := call("base_convert", types.NewString("ff"), types.NewInt(16), types.NewInt(2)); r.ToString() != "11111111" {
+		t.Errorf("base_convert('ff', 16, 2) = %q", r.ToString())
+	}
+	if r := call("intdiv", types.NewInt(7), types.NewInt(2)); r.ToInt() != 3 {
+		t.Errorf("intdiv(7, 2) = %d, want 3", r.ToInt())
+	}
+
+	call("mt_srand", types.NewInt(1))
+	if r := call("mt_rand"); r.ToInt() != 895547922 {
+		t.Errorf("mt_rand() after mt_srand(1) = %d, want 895547922", r.ToInt())
+	}
+}
+
+func TestMathBuiltins_ThrowErrors(t *testing.T) {
+	vm := New()
+
+	tests := []struct {
+		name    string
+		args    []*types.Value
+		class   string
+		message string
+	}{
+		{"intdiv", []*types.Value{types.NewInt(1), types.NewInt(0)}, "DivisionByZeroError", "Division by zero"},
+		{"min", []*types.Value{types.NewArray(types.NewEmptyArray())}, "ValueError", "min(): Argument #1 ($value) must contain at least one element"},
+		{"base_convert", []*types.Value{types.NewString("1"), types.NewInt(1), types.NewInt(2)}, "ValueError", "base_convert(): Argument #2 ($from_base) must be between 2 and 36 (inclusive)"},
+	}
+
+	for _, tt := range tests {
+		_, err := vm.CallCallable(types.NewString(tt.name), tt.args)
+		throwable, ok := err.(*ThrowableError)
+		if !ok || throwable.Object.ClassEntry.Name != tt.class {
+			t.Errorf("%s(): expected a %s, got %v", tt.name, tt.class, err)
+			continue
+		}
+		if msg := throwableProperty(throwable.Object, "message").ToString(); msg != tt.message {
+			t.Errorf("%s(): expected message %q, got %q", tt.name, tt.message, msg)
+		}
+	}
+}
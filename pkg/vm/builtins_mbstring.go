@@ -0,0 +1,82 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/stdlib/mbstring"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Multibyte String Builtins
+// ============================================================================
+
+// mbstringFunction is an mbstring function taking its required arguments
+// followed by the optional ones
+type mbstringFunction struct {
+	name     string
+	required int
+	call     func(args []*types.Value) (*types.Value, error)
+}
+
+var mbstringFunctions = []mbstringFunction{
+	{"mb_strlen", 1, func(args []*types.Value) (*types.Value, error) {
+		return mbstring.MbStrlen(args[0], args[1:]...)
+	}},
+	{"mb_substr", 2, func(args []*types.Value) (*types.Value, error) {
+		return mbstring.MbSubstr(args[0], args[1], args[2:]...)
+	}},
+	{"mb_str_split", 1, func(args []*types.Value) (*types.Value, error) {
+		return mbstring.MbStrSplit(args[0], args[1:]...)
+	}},
+	{"mb_strpos", 2, func(args []*types.Value) (*types.Value, error) {
+		return mbstring.MbStrpos(args[0], args[1], args[2:]...)
+	}},
+	{"mb_stripos", 2, func(args []*types.Value) (*types.Value, error) {
+		return mbstring.MbStripos(args[0], args[1], args[2:]...)
+	}},
+	{"mb_strrpos", 2, func(args []*types.Value) (*types.Value, error) {
+		return mbstring.MbStrrpos(args[0], args[1], args[2:]...)
+	}},
+	{"mb_convert_case", 2, func(args []*types.Value) (*types.Value, error) {
+		return mbstring.MbConvertCase(args[0], args[1], args[2:]...)
+	}},
+	{"mb_strtoupper", 1, func(args []*types.Value) (*types.Value, error) {
+		return mbstring.MbStrtoupper(args[0], args[1:]...)
+	}},
+	{"mb_strtolower", 1, func(args []*types.Value) (*types.Value, error) {
+		return mbstring.MbStrtolower(args[0], args[1:]...)
+	}},
+	{"mb_convert_encoding", 2, func(args []*types.Value) (*types.Value, error) {
+		return mbstring.MbConvertEncoding(args[0], args[1], args[2:]...)
+	}},
+	{"mb_check_encoding", 0, func(args []*types.Value) (*types.Value, error) {
+		if len(args) == 0 {
+			return mbstring.MbCheckEncoding(nil)
+		}
+		return mbstring.MbCheckEncoding(args[0], args[1:]...)
+	}},
+	{"mb_internal_encoding", 0, func(args []*types.Value) (*types.Value, error) {
+		return mbstring.MbInternalEncoding(args...)
+	}},
+	{"mb_list_encodings", 0, func(args []*types.Value) (*types.Value, error) {
+		return mbstring.MbListEncodings(), nil
+	}},
+}
+
+// registerMbstringBuiltins registers the multibyte string functions.
+// Argument errors are thrown as ValueErrors.
+func (vm *VM) registerMbstringBuiltins() {
+	for _, fn := range mbstringFunctions {
+		vm.RegisterBuiltin(fn.name, func(vm *VM, args []*types.Value) (*types.Value, error) {
+			if len(args) < fn.required {
+				return nil, fmt.Errorf("%s() expects at least %d arguments, %d given", fn.name, fn.required, len(args))
+			}
+			result, err := fn.call(derefArgs(args))
+			if e, ok := err.(*mbstring.Error); ok {
+				return nil, vm.ThrowError("ValueError", "%s(): %s", fn.name, e.Message)
+			}
+			return result, err
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/stdlib/mbstring"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestMbstringBuiltins(t *testing.T) {
+	vm := New()
+	defer mbstring.SetInternalEncoding(mbstring.UTF8)
+
+	call := func(name string, args ...*types.Value) *types.Value {
+		t.Helper()
+		result, err := vm.CallCallable(types.NewString(name), args)
+		if err != nil {
+			t.Fatalf("%s() failed: %v", name, err)
+		}
+		return result
+	}
+
+	if n := call("mb_strlen", types.NewString("日本語")); n.ToInt() != 3 {
+		t.Errorf("mb_strlen() = %d, want 3", n.ToInt())
+	}
+	if s := call("mb_substr", types.NewString("日本語"), types.NewInt(1), types.NewNull(), types.NewString("UTF-8")); s.ToString() != "本語" {
+		t.Errorf("mb_substr() = %q", s.ToString())
+	}
+	if s := call("mb_strtoupper", types.NewString("grüße")); s.ToString() != "GRÜSSE" {
+		t.Errorf("mb_strtoupper() = %q", s.ToString())
+	}
+	if ok := call("mb_internal_encoding", types.NewString("ISO-8859-1")); !ok.ToBool() {
+		t.Fatalf("mb_internal_encoding() failed")
+	}
+	if n := call("mb_strlen", types.NewString("日本語")); n.ToInt() != 9 {
+		t.Errorf("mb_strlen() in ISO-8859-1 = %d, want 9", n.ToInt())
+	}
+}
+
+func TestMbstringBuiltins_ThrowValueError(t *testing.T) {
+	vm := New()
+
+	_, err := vm.CallCallable(types.NewString("mb_strlen"), []*types.Value{types.NewString("a"), types.NewString("bogus")})
+	throwable, ok := err.(*ThrowableError)
+	if !ok || throwable.Object.ClassEntry.Name != "ValueError" {
+		t.Fatalf("Expected a ValueError, got %v", err)
+	}
+	expected := `mb_strlen(): Argument #2 ($encoding) must be a valid encoding, "bogus" given`
+	if msg := throwableProperty(throwable.Object, "message").ToString(); msg != expected {
+		t.Errorf("Expected message %q, got %q", expected, msg)
+	}
+}
@@ -0,0 +1,307 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	stdparallel "github.com/krizos/php-go/pkg/stdlib/parallel"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Parallel Tasks
+// ============================================================================
+
+// parallelFuture is the payload of parallel\Future objects
+type parallelFuture struct {
+	future *stdparallel.Future
+	output *[]byte    // Output of the task, complete once the future is done
+	flush  *sync.Once // Appends the output to the first VM collecting the result
+}
+
+// Transfer shares the future between tasks
+func (f *parallelFuture) Transfer() (interface{}, error) {
+	return f, nil
+}
+
+// Transfer copies a closure for another task: the compiled code is shared,
+// captured variables and the bound object are copied
+func (c *Closure) Transfer() (interface{}, error) {
+	copied := *c
+	copied.CapturedVars = make(map[string]*types.Value, len(c.CapturedVars))
+	for name, value := range c.CapturedVars {
+		v, err := stdparallel.Transfer(value)
+		if err != nil {
+			return nil, err
+		}
+		copied.CapturedVars[name] = v
+	}
+	if c.StaticVars != nil {
+		copied.StaticVars = make(map[string]*types.Value, len(c.StaticVars))
+		for name, slot := range c.StaticVars {
+			v, err := stdparallel.Transfer(slot.Deref())
+			if err != nil {
+				return nil, err
+			}
+			copied.StaticVars[name] = types.NewReference(v)
+		}
+	}
+	if c.This != nil {
+		this, err := stdparallel.Transfer(types.NewObject(c.This))
+		if err != nil {
+			return nil, err
+		}
+		copied.This = this.ToObject()
+	}
+	return &copied, nil
+}
+
+// fork creates a VM that runs a task on another goroutine. It sees the
+// functions, classes and constants declared so far, but has its own globals,
+// call stack and output. Static properties live on the shared class entries,
+// so tasks must not modify them.
+func (vm *VM) fork() *VM {
+	child := New()
+	// A full slice expression makes appends by the child reallocate
+	child.constants = vm.constants[:len(vm.constants):len(vm.constants)]
+	for name, fn := range vm.functions {
+		child.functions[name] = fn
+	}
+	// The child keeps its own STDOUT, writing to its output
+	for name, value := range vm.definedConstants {
+		if _, builtin := child.definedConstants[name]; !builtin {
+			child.definedConstants[name] = value
+		}
+	}
+	for name, builtin := range vm.builtins {
+		child.builtins[name] = builtin
+	}
+	for name, class := range vm.classes {
+		child.classes[name] = class
+	}
+	child.program = vm.program
+	child.scriptPath = vm.scriptPath
+	child.compileScript = vm.compileScript
+	child.scriptCache = vm.scriptCache
+	child.includePath = append([]string(nil), vm.includePath...)
+	return child
+}
+
+// runParallel starts a task calling a closure with arguments on a forked VM
+func (vm *VM) runParallel(task *types.Value, args []*types.Value) (*types.Value, error) {
+	task, err := stdparallel.Transfer(task)
+	if err != nil {
+		return nil, vm.ThrowError("parallel\\Error", "parallel\\run(): %v", err)
+	}
+	argv := make([]*types.Value, len(args))
+	for i, arg := range args {
+		if argv[i], err = stdparallel.Transfer(arg); err != nil {
+			return nil, vm.ThrowError("parallel\\Error", "parallel\\run(): %v", err)
+		}
+	}
+
+	child := vm.fork()
+	output := new([]byte)
+	future := stdparallel.Go(func() (*types.Value, error) {
+		defer func() { *output = child.output }()
+		return child.CallCallable(task, argv)
+	})
+
+	obj := types.NewObjectFromClass(vm.classes["parallel\\Future"])
+	obj.Internal = &parallelFuture{future: future, output: output, flush: new(sync.Once)}
+	return types.NewObject(obj), nil
+}
+
+// ============================================================================
+// Parallel Builtins
+// ============================================================================
+
+// registerParallelBuiltins registers the parallel\ namespace: run(), the
+// Future and Channel classes and their exceptions
+func (vm *VM) registerParallelBuiltins() {
+	parallelError := types.NewClassEntry("parallel\\Error")
+	parallelError.InheritFrom(vm.classes["Error"])
+	vm.classes[parallelError.Name] = parallelError
+
+	closed := types.NewClassEntry("parallel\\Channel\\Error\\Closed")
+	closed.InheritFrom(parallelError)
+	vm.classes[closed.Name] = closed
+
+	future := types.NewClassEntry("parallel\\Future")
+	future.IsFinal = true
+	addNativeMethod(future, "value", 0, futureValue)
+	addNativeMethod(future, "done", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(futureOf(this).future.Done()), nil
+	})
+	vm.classes[future.Name] = future
+
+	channel := types.NewClassEntry("parallel\\Channel")
+	channel.IsFinal = true
+	addNativeMethod(channel, "__construct", 2, channelConstruct)
+	channel.Constructor = channel.Methods["__construct"]
+	channel.Constructor.IsConstructor = true
+	addNativeMethod(channel, "make", 3, channelMake).IsStatic = true
+	addNativeMethod(channel, "open", 1, channelOpen).IsStatic = true
+	addNativeMethod(channel, "send", 1, channelSend)
+	addNativeMethod(channel, "recv", 0, channelRecv)
+	addNativeMethod(channel, "close", 0, channelClose)
+	addNativeMethod(channel, "__toString", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		c := channelOf(this)
+		if c.Name() != "" {
+			return types.NewString(c.Name()), nil
+		}
+		return types.NewString(fmt.Sprintf("channel#%d", this.ObjectID)), nil
+	}).IsMagic = true
+	vm.classes[channel.Name] = channel
+
+	vm.RegisterBuiltin("parallel\\run", builtinParallelRun)
+}
+
+// parallel\run(Closure $task, array $argv = []): parallel\Future
+func builtinParallelRun(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) == 0 {
+		return nil, vm.ThrowError("ArgumentCountError", "parallel\\run() expects at least 1 argument, 0 given")
+	}
+	task := args[0].Deref()
+	if !task.IsObject() {
+		return nil, vm.ThrowError("TypeError", "parallel\\run(): Argument #1 ($task) must be of type Closure, %s given", task.TypeString())
+	}
+	if _, ok := task.ToObject().Internal.(*Closure); !ok {
+		return nil, vm.ThrowError("TypeError", "parallel\\run(): Argument #1 ($task) must be of type Closure, %s given", task.ToObject().ClassName)
+	}
+
+	var argv []*types.Value
+	if len(args) > 1 {
+		if !args[1].Deref().IsArray() {
+			return nil, vm.ThrowError("TypeError", "parallel\\run(): Argument #2 ($argv) must be of type array, %s given", args[1].Deref().TypeString())
+		}
+		args[1].Deref().ToArray().Each(func(key, value *types.Value) bool {
+			argv = append(argv, value)
+			return true
+		})
+	}
+	return vm.runParallel(task, argv)
+}
+
+// futureOf returns the payload of a parallel\Future object
+func futureOf(obj *types.Object) *parallelFuture {
+	f, _ := obj.Internal.(*parallelFuture)
+	return f
+}
+
+// Future::value(): mixed
+// Waits for the task, writes its output and returns its result. Exceptions
+// thrown by the task are rethrown.
+func futureValue(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	f := futureOf(this)
+	if f == nil {
+		return nil, vm.ThrowError("parallel\\Error", "Future is not initialized")
+	}
+
+	value, err := f.future.Value()
+	f.flush.Do(func() {
+		vm.writeOutput(*f.output)
+	})
+	if err != nil {
+		var throwable *ThrowableError
+		if errors.As(err, &throwable) {
+			return nil, throwable
+		}
+		return nil, vm.ThrowError("parallel\\Error", "%v", err)
+	}
+	return value, nil
+}
+
+// channelOf returns the payload of a parallel\Channel object
+func channelOf(obj *types.Object) *stdparallel.Channel {
+	c, _ := obj.Internal.(*stdparallel.Channel)
+	return c
+}
+
+// channelObject wraps a channel in a parallel\Channel object
+func (vm *VM) channelObject(c *stdparallel.Channel) *types.Value {
+	obj := types.NewObjectFromClass(vm.classes["parallel\\Channel"])
+	obj.Internal = c
+	return types.NewObject(obj)
+}
+
+// channelArgs reads the optional capacity and element type arguments
+func channelArgs(args []*types.Value) (int, string) {
+	capacity, elemType := 0, ""
+	if len(args) > 0 {
+		capacity = int(args[0].Deref().ToInt())
+	}
+	if len(args) > 1 && !args[1].Deref().IsNull() {
+		elemType = args[1].Deref().ToString()
+	}
+	return capacity, elemType
+}
+
+// Channel::__construct(int $capacity = 0, ?string $type = null)
+func channelConstruct(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	this.Internal = stdparallel.NewTypedChannel(channelArgs(args))
+	return types.NewNull(), nil
+}
+
+// Channel::make(string $name, int $capacity = 0, ?string $type = null): Channel
+func channelMake(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	if len(args) == 0 {
+		return nil, vm.ThrowError("ArgumentCountError", "parallel\\Channel::make() expects at least 1 argument, 0 given")
+	}
+	capacity, elemType := channelArgs(args[1:])
+	c, err := stdparallel.MakeChannel(args[0].Deref().ToString(), capacity, elemType)
+	if err != nil {
+		return nil, vm.ThrowError("parallel\\Error", "%v", err)
+	}
+	return vm.channelObject(c), nil
+}
+
+// Channel::open(string $name): Channel
+func channelOpen(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	if len(args) == 0 {
+		return nil, vm.ThrowError("ArgumentCountError", "parallel\\Channel::open() expects exactly 1 argument, 0 given")
+	}
+	c, err := stdparallel.OpenChannel(args[0].Deref().ToString())
+	if err != nil {
+		return nil, vm.ThrowError("parallel\\Error", "%v", err)
+	}
+	return vm.channelObject(c), nil
+}
+
+// Channel::send(mixed $value): void
+func channelSend(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	value := types.NewNull()
+	if len(args) > 0 {
+		value = args[0]
+	}
+	if err := channelOf(this).Send(value); err != nil {
+		return nil, vm.channelError(err)
+	}
+	return types.NewNull(), nil
+}
+
+// Channel::recv(): mixed
+func channelRecv(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	value, err := channelOf(this).Recv()
+	if err != nil {
+		return nil, vm.channelError(err)
+	}
+	return value, nil
+}
+
+// Channel::close(): void
+func channelClose(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	if err := channelOf(this).Close(); err != nil {
+		return nil, vm.channelError(err)
+	}
+	return types.NewNull(), nil
+}
+
+// channelError converts a channel error into the matching PHP exception
+func (vm *VM) channelError(err error) error {
+	if errors.Is(err, stdparallel.ErrClosed) {
+		return vm.ThrowError("parallel\\Channel\\Error\\Closed", "channel is closed")
+	}
+	return vm.ThrowError("parallel\\Error", "%v", err)
+}
@@ -0,0 +1,138 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// callParallel calls a parallel\ function or method, failing the test on error
+func callParallel(t *testing.T, vm *VM, callable *types.Value, args ...*types.Value) *types.Value {
+	t.Helper()
+	result, err := vm.CallCallable(callable, args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return result
+}
+
+func TestParallelRun_ReturnsResultAndOutput(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"from task "}
+
+	// function ($x) { echo "from task "; return $x + $x; }
+	fn := doubleFunction("{closure}")
+	fn.Instructions = append(Instructions{{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 0}}}, fn.Instructions...)
+	task := newClosureObject(&Closure{Function: fn, CapturedVars: make(map[string]*types.Value), Static: true})
+
+	argv := types.NewArray(types.NewArrayFromSlice([]*types.Value{types.NewInt(21)}))
+	future := callParallel(t, vm, types.NewString("parallel\\run"), task, argv)
+	if future.ToObject().ClassName != "parallel\\Future" {
+		t.Fatalf("Expected a parallel\\Future, got %s", future.ToObject().ClassName)
+	}
+
+	result := callParallel(t, vm, callableArray(future, "value"))
+	if result.ToInt() != 42 {
+		t.Errorf("Expected 42, got %v", result)
+	}
+	if !callParallel(t, vm, callableArray(future, "done")).ToBool() {
+		t.Error("Expected the future to be done")
+	}
+
+	// The output is written once, by the first value() call
+	callParallel(t, vm, callableArray(future, "value"))
+	if vm.GetOutput() != "from task " {
+		t.Errorf("Expected task output once, got %q", vm.GetOutput())
+	}
+}
+
+func TestParallelRun_RethrowsTaskException(t *testing.T) {
+	vm := New()
+	task := newClosureObject(&Closure{
+		Builtin: func(vm *VM, args []*types.Value) (*types.Value, error) {
+			return nil, vm.ThrowError("RuntimeException", "failed in task")
+		},
+		CapturedVars: make(map[string]*types.Value),
+	})
+
+	future := callParallel(t, vm, types.NewString("parallel\\run"), task)
+	_, err := vm.CallCallable(callableArray(future, "value"), nil)
+	throwable, ok := err.(*ThrowableError)
+	if !ok || throwable.Object.ClassEntry.Name != "RuntimeException" {
+		t.Fatalf("Expected the task's RuntimeException, got %v", err)
+	}
+	if msg := throwableProperty(throwable.Object, "message").ToString(); msg != "failed in task" {
+		t.Errorf("Expected message 'failed in task', got %q", msg)
+	}
+
+	if _, err := vm.CallCallable(types.NewString("parallel\\run"), []*types.Value{types.NewString("strlen")}); err == nil {
+		t.Error("Expected a TypeError for a task that is not a Closure")
+	}
+}
+
+func TestParallelChannel_ProducerConsumer(t *testing.T) {
+	vm := New()
+	channel := callParallel(t, vm, types.NewString("parallel\\Channel::make"), types.NewString(t.Name()), types.NewInt(1))
+
+	// The producer receives its own copy of the payload and shares the channel
+	producer := newClosureObject(&Closure{
+		Builtin: func(vm *VM, args []*types.Value) (*types.Value, error) {
+			ch := args[0]
+			payload := args[1].ToArray()
+			for i := int64(1); i <= 3; i++ {
+				payload.Set(types.NewString("n"), types.NewInt(i))
+				if _, err := vm.CallCallable(callableArray(ch, "send"), []*types.Value{types.NewArray(payload)}); err != nil {
+					return nil, err
+				}
+			}
+			return vm.CallCallable(callableArray(ch, "close"), nil)
+		},
+		CapturedVars: make(map[string]*types.Value),
+	})
+
+	payload := types.NewEmptyArray()
+	argv := types.NewArray(types.NewArrayFromSlice([]*types.Value{channel, types.NewArray(payload)}))
+	future := callParallel(t, vm, types.NewString("parallel\\run"), producer, argv)
+
+	var received []int64
+	for {
+		value, err := vm.CallCallable(callableArray(channel, "recv"), nil)
+		if err != nil {
+			throwable, ok := err.(*ThrowableError)
+			if !ok || throwable.Object.ClassEntry.Name != "parallel\\Channel\\Error\\Closed" {
+				t.Fatalf("Expected parallel\\Channel\\Error\\Closed, got %v", err)
+			}
+			break
+		}
+		n, _ := value.ToArray().Get(types.NewString("n"))
+		received = append(received, n.ToInt())
+	}
+	callParallel(t, vm, callableArray(future, "value"))
+
+	if len(received) != 3 || received[0] != 1 || received[2] != 3 {
+		t.Errorf("Expected to receive 1, 2, 3, got %v", received)
+	}
+	if payload.Len() != 0 {
+		t.Errorf("Expected the parent's payload to be unchanged, got %s", payload)
+	}
+
+	opened := callParallel(t, vm, types.NewString("parallel\\Channel::open"), types.NewString(t.Name()))
+	if opened.ToObject().Internal != channel.ToObject().Internal {
+		t.Error("Expected Channel::open to return the named channel")
+	}
+}
+
+func TestFork_OwnStdout(t *testing.T) {
+	vm := New()
+	vm.DefineConstant("USER_CONSTANT", types.NewInt(1))
+	child := vm.fork()
+
+	parentStdout, _ := vm.LookupConstant("STDOUT")
+	childStdout, _ := child.LookupConstant("STDOUT")
+	if parentStdout == childStdout {
+		t.Error("Expected the task to have its own STDOUT")
+	}
+	if _, ok := child.LookupConstant("USER_CONSTANT"); !ok {
+		t.Error("Expected the task to see the constants defined so far")
+	}
+}
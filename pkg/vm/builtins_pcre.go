@@ -0,0 +1,114 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/stdlib/pcre"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// PCRE Builtins
+// ============================================================================
+
+// registerPcreBuiltins registers the preg_* functions. $matches and $count
+// are written through the by-reference arguments.
+func (vm *VM) registerPcreBuiltins() {
+	vm.RegisterBuiltin("preg_match", builtinPregMatch)
+	vm.RegisterBuiltin("preg_match_all", builtinPregMatchAll)
+	vm.RegisterBuiltin("preg_replace", builtinPregReplace)
+	vm.RegisterBuiltin("preg_replace_callback", builtinPregReplaceCallback)
+	vm.RegisterBuiltin("preg_split", builtinPregSplit)
+	vm.RegisterBuiltin("preg_quote", builtinPregQuote)
+	vm.RegisterBuiltin("preg_grep", builtinPregGrep)
+	vm.RegisterBuiltin("preg_last_error", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return pcre.PregLastError(), nil
+	})
+	vm.RegisterBuiltin("preg_last_error_msg", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return pcre.PregLastErrorMsg(), nil
+	})
+}
+
+// preg_match(string $pattern, string $subject, array &$matches = null, int $flags = 0, int $offset = 0): int|false
+func builtinPregMatch(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("preg_match() expects at least 2 arguments, %d given", len(args))
+	}
+	result, matches := pcre.PregMatch(args[0].Deref(), args[1].Deref(), optionalArgs(args, 3, 5)...)
+	assignRefArg(args, 2, matches)
+	return result, nil
+}
+
+// preg_match_all(string $pattern, string $subject, array &$matches = null, int $flags = 0, int $offset = 0): int|false
+func builtinPregMatchAll(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("preg_match_all() expects at least 2 arguments, %d given", len(args))
+	}
+	result, matches := pcre.PregMatchAll(args[0].Deref(), args[1].Deref(), optionalArgs(args, 3, 5)...)
+	assignRefArg(args, 2, matches)
+	return result, nil
+}
+
+// preg_replace(string|array $pattern, string|array $replacement, string|array $subject, int $limit = -1, int &$count = null): string|array|null
+func builtinPregReplace(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("preg_replace() expects at least 3 arguments, %d given", len(args))
+	}
+	result, count := pcre.PregReplace(args[0].Deref(), args[1].Deref(), args[2].Deref(), optionalArgs(args, 3, 4)...)
+	assignRefArg(args, 4, count)
+	return result, nil
+}
+
+// preg_replace_callback(string|array $pattern, callable $callback, string|array $subject, int $limit = -1, int &$count = null, int $flags = 0): string|array|null
+func builtinPregReplaceCallback(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("preg_replace_callback() expects at least 3 arguments, %d given", len(args))
+	}
+	// $limit and $flags, skipping $count
+	var options []*types.Value
+	if len(args) > 3 {
+		options = append(options, args[3].Deref())
+	}
+	if len(args) > 5 {
+		options = append(options, args[5].Deref())
+	}
+	result, count, err := pcre.PregReplaceCallback(vm, args[0].Deref(), args[1], args[2].Deref(), options...)
+	if err != nil {
+		return nil, err
+	}
+	assignRefArg(args, 4, count)
+	return result, nil
+}
+
+// preg_split(string $pattern, string $subject, int $limit = -1, int $flags = 0): array|false
+func builtinPregSplit(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("preg_split() expects at least 2 arguments, %d given", len(args))
+	}
+	return pcre.PregSplit(args[0].Deref(), args[1].Deref(), derefArgs(args[2:])...), nil
+}
+
+// preg_quote(string $str, ?string $delimiter = null): string
+func builtinPregQuote(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("preg_quote() expects at least 1 argument, 0 given")
+	}
+	return pcre.PregQuote(args[0].Deref(), derefArgs(args[1:])...), nil
+}
+
+// preg_grep(string $pattern, array $array, int $flags = 0): array|false
+func builtinPregGrep(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("preg_grep() expects at least 2 arguments, %d given", len(args))
+	}
+	return pcre.PregGrep(args[0].Deref(), args[1].Deref(), derefArgs(args[2:])...), nil
+}
+
+// optionalArgs returns the dereferenced arguments from index from up to (not
+// including) index to, if they were passed
+func optionalArgs(args []*types.Value, from, to int) []*types.Value {
+	if len(args) <= from {
+		return nil
+	}
+	return derefArgs(args[from:min(len(args), to)])
+}
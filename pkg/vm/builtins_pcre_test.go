@@ -0,0 +1,54 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestPregMatch_WritesMatchesReference(t *testing.T) {
+	vm := New()
+	matches := types.NewReference(types.NewNull())
+
+	result, err := vm.CallCallable(types.NewString("preg_match"), []*types.Value{
+		types.NewString(`/(?<year>\d{4})-(\d{2})/`), types.NewString("on 2024-05"), matches,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ToInt() != 1 {
+		t.Fatalf("Expected 1, got %v", result)
+	}
+	year, _ := matches.Deref().ToArray().Get(types.NewString("year"))
+	if year == nil || year.ToString() != "2024" {
+		t.Errorf("Expected $matches['year'] to be 2024, got %v", matches.Deref())
+	}
+}
+
+func TestPregReplaceCallback_CallsCallbackAndCounts(t *testing.T) {
+	vm := New()
+	count := types.NewReference(types.NewNull())
+
+	// fn ($m) => strtoupper($m[0])
+	callback := newClosureObject(&Closure{
+		Builtin: func(vm *VM, args []*types.Value) (*types.Value, error) {
+			match, _ := args[0].ToArray().Get(types.NewInt(0))
+			return types.NewString(strings.ToUpper(match.ToString())), nil
+		},
+		CapturedVars: make(map[string]*types.Value),
+	})
+
+	result, err := vm.CallCallable(types.NewString("preg_replace_callback"), []*types.Value{
+		types.NewString(`/\w+/`), callback, types.NewString("ab cde"), types.NewInt(-1), count,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ToString() != "AB CDE" {
+		t.Errorf("Expected 'AB CDE', got %q", result.ToString())
+	}
+	if count.Deref().ToInt() != 2 {
+		t.Errorf("Expected $count to be 2, got %v", count.Deref())
+	}
+}
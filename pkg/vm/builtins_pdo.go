@@ -0,0 +1,478 @@
+package vm
+
+import (
+	"errors"
+
+	stdpdo "github.com/krizos/php-go/pkg/stdlib/pdo"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// PDO Classes
+// ============================================================================
+
+// pdoConstants are the class constants of PDO
+var pdoConstants = []struct {
+	name  string
+	value int64
+}{
+	{"PARAM_NULL", stdpdo.PARAM_NULL},
+	{"PARAM_INT", stdpdo.PARAM_INT},
+	{"PARAM_STR", stdpdo.PARAM_STR},
+	{"PARAM_LOB", stdpdo.PARAM_LOB},
+	{"PARAM_BOOL", stdpdo.PARAM_BOOL},
+	{"FETCH_DEFAULT", stdpdo.FETCH_DEFAULT},
+	{"FETCH_LAZY", stdpdo.FETCH_LAZY},
+	{"FETCH_ASSOC", stdpdo.FETCH_ASSOC},
+	{"FETCH_NUM", stdpdo.FETCH_NUM},
+	{"FETCH_BOTH", stdpdo.FETCH_BOTH},
+	{"FETCH_OBJ", stdpdo.FETCH_OBJ},
+	{"FETCH_COLUMN", stdpdo.FETCH_COLUMN},
+	{"FETCH_CLASS", stdpdo.FETCH_CLASS},
+	{"FETCH_KEY_PAIR", stdpdo.FETCH_KEY_PAIR},
+	{"FETCH_GROUP", stdpdo.FETCH_GROUP},
+	{"FETCH_UNIQUE", stdpdo.FETCH_UNIQUE},
+	{"FETCH_ORI_NEXT", stdpdo.FETCH_ORI_NEXT},
+	{"FETCH_ORI_FIRST", stdpdo.FETCH_ORI_FIRST},
+	{"ATTR_AUTOCOMMIT", stdpdo.ATTR_AUTOCOMMIT},
+	{"ATTR_ERRMODE", stdpdo.ATTR_ERRMODE},
+	{"ATTR_CASE", stdpdo.ATTR_CASE},
+	{"ATTR_DRIVER_NAME", stdpdo.ATTR_DRIVER_NAME},
+	{"ATTR_STRINGIFY_FETCHES", stdpdo.ATTR_STRINGIFY_FETCHES},
+	{"ATTR_DEFAULT_FETCH_MODE", stdpdo.ATTR_DEFAULT_FETCH_MODE},
+	{"ATTR_EMULATE_PREPARES", stdpdo.ATTR_EMULATE_PREPARES},
+	{"ERRMODE_SILENT", stdpdo.ERRMODE_SILENT},
+	{"ERRMODE_WARNING", stdpdo.ERRMODE_WARNING},
+	{"ERRMODE_EXCEPTION", stdpdo.ERRMODE_EXCEPTION},
+	{"CASE_NATURAL", stdpdo.CASE_NATURAL},
+	{"CASE_UPPER", stdpdo.CASE_UPPER},
+	{"CASE_LOWER", stdpdo.CASE_LOWER},
+}
+
+// registerPdoClasses registers PDO, PDOStatement and PDOException
+func (vm *VM) registerPdoClasses() {
+	exception := types.NewClassEntry("PDOException")
+	exception.InheritFrom(vm.classes["RuntimeException"])
+	exception.Constructor = exception.ParentClass.Constructor
+	exception.Methods["__construct"] = exception.Constructor
+	exception.Properties["errorInfo"] = &types.PropertyDef{
+		Name: "errorInfo", Visibility: types.VisibilityPublic,
+		HasDefault: true, Default: types.NewNull(), DeclaringClass: exception.Name,
+	}
+	vm.classes[exception.Name] = exception
+
+	pdo := types.NewClassEntry("PDO")
+	for _, c := range pdoConstants {
+		pdo.Constants[c.name] = &types.ClassConstant{Name: c.name, Value: types.NewInt(c.value), Visibility: types.VisibilityPublic}
+	}
+	addNativeMethod(pdo, "__construct", 4, pdoConstruct)
+	pdo.Constructor = pdo.Methods["__construct"]
+	pdo.Constructor.IsConstructor = true
+	addNativeMethod(pdo, "prepare", 2, pdoPrepare)
+	addNativeMethod(pdo, "query", 2, pdoQuery)
+	addNativeMethod(pdo, "exec", 1, pdoExec)
+	addNativeMethod(pdo, "beginTransaction", 0, pdoTransaction((*stdpdo.Conn).Begin))
+	addNativeMethod(pdo, "commit", 0, pdoTransaction((*stdpdo.Conn).Commit))
+	addNativeMethod(pdo, "rollBack", 0, pdoTransaction((*stdpdo.Conn).Rollback))
+	addNativeMethod(pdo, "inTransaction", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		conn, err := vm.pdoConn(this)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewBool(conn.InTransaction()), nil
+	})
+	addNativeMethod(pdo, "lastInsertId", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		conn, err := vm.pdoConn(this)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewString(conn.LastInsertID()), nil
+	})
+	addNativeMethod(pdo, "quote", 2, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		conn, err := vm.pdoConn(this)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 0 {
+			return nil, vm.ThrowError("ArgumentCountError", "PDO::quote() expects at least 1 argument, 0 given")
+		}
+		return types.NewString(conn.Quote(args[0].Deref().ToString())), nil
+	})
+	addNativeMethod(pdo, "errorCode", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		conn, err := vm.pdoConn(this)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewString(conn.LastError().SQLState), nil
+	})
+	addNativeMethod(pdo, "errorInfo", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		conn, err := vm.pdoConn(this)
+		if err != nil {
+			return nil, err
+		}
+		return conn.LastError().Info(), nil
+	})
+	addNativeMethod(pdo, "setAttribute", 2, pdoSetAttribute)
+	addNativeMethod(pdo, "getAttribute", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		conn, err := vm.pdoConn(this)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 0 {
+			return nil, vm.ThrowError("ArgumentCountError", "PDO::getAttribute() expects exactly 1 argument, 0 given")
+		}
+		return conn.Attribute(int(args[0].Deref().ToInt())), nil
+	})
+	addNativeMethod(pdo, "getAvailableDrivers", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		drivers := types.NewEmptyArray()
+		for _, name := range stdpdo.AvailableDrivers() {
+			drivers.Append(types.NewString(name))
+		}
+		return types.NewArray(drivers), nil
+	}).IsStatic = true
+	vm.classes[pdo.Name] = pdo
+
+	stmt := types.NewClassEntry("PDOStatement")
+	stmt.Properties["queryString"] = &types.PropertyDef{
+		Name: "queryString", Visibility: types.VisibilityPublic, DeclaringClass: stmt.Name,
+	}
+	addNativeMethod(stmt, "execute", 1, stmtExecute)
+	addNativeMethod(stmt, "bindValue", 3, stmtBind(false))
+	addNativeMethod(stmt, "bindParam", 3, stmtBind(true)).Parameters = []*types.ParameterDef{
+		{Name: "param"},
+		{Name: "var", PassedByRef: true},
+		{Name: "type", HasDefault: true, Default: types.NewInt(stdpdo.PARAM_STR)},
+	}
+	addNativeMethod(stmt, "fetch", 1, stmtFetch)
+	addNativeMethod(stmt, "fetchAll", 2, stmtFetchAll)
+	addNativeMethod(stmt, "fetchColumn", 1, stmtFetchColumn)
+	addNativeMethod(stmt, "fetchObject", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return stmtFetch(vm, this, []*types.Value{types.NewInt(stdpdo.FETCH_OBJ)})
+	})
+	addNativeMethod(stmt, "setFetchMode", 2, stmtSetFetchMode)
+	addNativeMethod(stmt, "rowCount", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		s, err := vm.pdoStatement(this)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewInt(s.RowCount()), nil
+	})
+	addNativeMethod(stmt, "columnCount", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		s, err := vm.pdoStatement(this)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewInt(int64(s.ColumnCount())), nil
+	})
+	addNativeMethod(stmt, "closeCursor", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		s, err := vm.pdoStatement(this)
+		if err != nil {
+			return nil, err
+		}
+		s.CloseCursor()
+		return types.NewBool(true), nil
+	})
+	addNativeMethod(stmt, "errorCode", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		s, err := vm.pdoStatement(this)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewString(s.LastError().SQLState), nil
+	})
+	addNativeMethod(stmt, "errorInfo", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		s, err := vm.pdoStatement(this)
+		if err != nil {
+			return nil, err
+		}
+		return s.LastError().Info(), nil
+	})
+	vm.classes[stmt.Name] = stmt
+}
+
+// pdoException converts a PDO error into a PDOException carrying the
+// SQLSTATE as its code and the errorInfo array
+func (vm *VM) pdoException(err error) error {
+	var pdoErr *stdpdo.Error
+	if !errors.As(err, &pdoErr) {
+		return vm.ThrowError("PDOException", "%v", err)
+	}
+	exception := vm.ThrowError("PDOException", "%s", pdoErr.Error())
+	obj := exception.(*ThrowableError).Object
+	setThrowableProperty(obj, "code", types.NewString(pdoErr.SQLState))
+	setThrowableProperty(obj, "errorInfo", pdoErr.Info())
+	return exception
+}
+
+// pdoFail reports a failed operation according to the connection's error
+// mode: a PDOException with ERRMODE_EXCEPTION, otherwise false
+func (vm *VM) pdoFail(conn *stdpdo.Conn, err error) (*types.Value, error) {
+	var pdoErr *stdpdo.Error
+	if errors.As(err, &pdoErr) && conn.ErrMode() != stdpdo.ERRMODE_EXCEPTION {
+		return types.NewBool(false), nil
+	}
+	return nil, vm.pdoException(err)
+}
+
+// pdoConn returns the connection of a PDO object
+func (vm *VM) pdoConn(this *types.Object) (*stdpdo.Conn, error) {
+	conn, ok := this.Internal.(*stdpdo.Conn)
+	if !ok {
+		return nil, vm.ThrowError("Error", "PDO object is uninitialized")
+	}
+	return conn, nil
+}
+
+// pdoStatement returns the statement of a PDOStatement object
+func (vm *VM) pdoStatement(this *types.Object) (*stdpdo.Statement, error) {
+	stmt, ok := this.Internal.(*stdpdo.Statement)
+	if !ok {
+		return nil, vm.ThrowError("Error", "PDOStatement object is uninitialized")
+	}
+	return stmt, nil
+}
+
+// statementObject wraps a statement in a PDOStatement object
+func (vm *VM) statementObject(stmt *stdpdo.Statement) *types.Value {
+	obj := types.NewObjectFromClass(vm.classes["PDOStatement"])
+	obj.Internal = stmt
+	obj.Properties["queryString"].Value = types.NewString(stmt.QueryString())
+	return types.NewObject(obj)
+}
+
+// intArg returns an optional integer argument
+func intArg(args []*types.Value, index int, def int) int {
+	if index >= len(args) || args[index].Deref().IsNull() {
+		return def
+	}
+	return int(args[index].Deref().ToInt())
+}
+
+// ============================================================================
+// PDO Methods
+// ============================================================================
+
+// PDO::__construct(string $dsn, ?string $username = null, ?string $password = null, ?array $options = null)
+// Connection errors always throw, whatever the error mode.
+func pdoConstruct(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	if len(args) == 0 {
+		return nil, vm.ThrowError("ArgumentCountError", "PDO::__construct() expects at least 1 argument, 0 given")
+	}
+	var user, password string
+	if len(args) > 1 {
+		user = args[1].Deref().ToString()
+	}
+	if len(args) > 2 {
+		password = args[2].Deref().ToString()
+	}
+
+	conn, err := stdpdo.Open(args[0].Deref().ToString(), user, password)
+	if err != nil {
+		return nil, vm.pdoException(err)
+	}
+	if len(args) > 3 && args[3].Deref().IsArray() {
+		var attrErr error
+		args[3].Deref().ToArray().Each(func(key, value *types.Value) bool {
+			attrErr = conn.SetAttribute(int(key.ToInt()), value.Deref())
+			return attrErr == nil
+		})
+		if attrErr != nil {
+			conn.Close()
+			return nil, vm.ThrowError("ValueError", "PDO::__construct(): %v", attrErr)
+		}
+	}
+	this.Internal = conn
+	return types.NewNull(), nil
+}
+
+// PDO::prepare(string $query, array $options = []): PDOStatement|false
+func pdoPrepare(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	conn, err := vm.pdoConn(this)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, vm.ThrowError("ArgumentCountError", "PDO::prepare() expects at least 1 argument, 0 given")
+	}
+	stmt, err := conn.Prepare(args[0].Deref().ToString())
+	if err != nil {
+		return vm.pdoFail(conn, err)
+	}
+	return vm.statementObject(stmt), nil
+}
+
+// PDO::query(string $query, ?int $fetchMode = null, mixed ...$fetchModeArgs): PDOStatement|false
+func pdoQuery(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	conn, err := vm.pdoConn(this)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, vm.ThrowError("ArgumentCountError", "PDO::query() expects at least 1 argument, 0 given")
+	}
+	stmt, err := conn.Query(args[0].Deref().ToString())
+	if err != nil {
+		return vm.pdoFail(conn, err)
+	}
+	if len(args) > 1 && !args[1].Deref().IsNull() {
+		var fetchArg *types.Value
+		if len(args) > 2 {
+			fetchArg = args[2].Deref()
+		}
+		stmt.SetFetchMode(int(args[1].Deref().ToInt()), fetchArg)
+	}
+	return vm.statementObject(stmt), nil
+}
+
+// PDO::exec(string $statement): int|false
+func pdoExec(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	conn, err := vm.pdoConn(this)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, vm.ThrowError("ArgumentCountError", "PDO::exec() expects exactly 1 argument, 0 given")
+	}
+	affected, err := conn.Exec(args[0].Deref().ToString())
+	if err != nil {
+		return vm.pdoFail(conn, err)
+	}
+	return types.NewInt(affected), nil
+}
+
+// pdoTransaction implements beginTransaction(), commit() and rollBack(): bool
+func pdoTransaction(op func(*stdpdo.Conn) error) NativeMethod {
+	return func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		conn, err := vm.pdoConn(this)
+		if err != nil {
+			return nil, err
+		}
+		if err := op(conn); err != nil {
+			// Transaction state errors throw in every error mode
+			return nil, vm.pdoException(err)
+		}
+		return types.NewBool(true), nil
+	}
+}
+
+// PDO::setAttribute(int $attribute, mixed $value): bool
+func pdoSetAttribute(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	conn, err := vm.pdoConn(this)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < 2 {
+		return nil, vm.ThrowError("ArgumentCountError", "PDO::setAttribute() expects exactly 2 arguments, %d given", len(args))
+	}
+	if err := conn.SetAttribute(int(args[0].Deref().ToInt()), args[1].Deref()); err != nil {
+		return nil, vm.ThrowError("ValueError", "PDO::setAttribute(): %v", err)
+	}
+	return types.NewBool(true), nil
+}
+
+// ============================================================================
+// PDOStatement Methods
+// ============================================================================
+
+// PDOStatement::execute(?array $params = null): bool
+func stmtExecute(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	stmt, err := vm.pdoStatement(this)
+	if err != nil {
+		return nil, err
+	}
+	var input *types.Array
+	if len(args) > 0 && args[0].Deref().IsArray() {
+		input = args[0].Deref().ToArray()
+	}
+	if err := stmt.Execute(input); err != nil {
+		return vm.pdoFail(stmt.Conn(), err)
+	}
+	return types.NewBool(true), nil
+}
+
+// stmtBind implements bindValue() and bindParam(), which binds the variable
+// itself, so its value when execute() runs is used
+// PDOStatement::bindValue(string|int $param, mixed $value, int $type = PDO::PARAM_STR): bool
+// PDOStatement::bindParam(string|int $param, mixed &$var, int $type = PDO::PARAM_STR): bool
+func stmtBind(byRef bool) NativeMethod {
+	name := "bindValue"
+	if byRef {
+		name = "bindParam"
+	}
+	return func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		stmt, err := vm.pdoStatement(this)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) < 2 {
+			return nil, vm.ThrowError("ArgumentCountError", "PDOStatement::%s() expects at least 2 arguments, %d given", name, len(args))
+		}
+		value := args[1]
+		if !byRef {
+			value = value.Deref().Copy()
+		}
+		if err := stmt.Bind(args[0].Deref(), value, intArg(args, 2, stdpdo.PARAM_STR)); err != nil {
+			return vm.pdoFail(stmt.Conn(), err)
+		}
+		return types.NewBool(true), nil
+	}
+}
+
+// PDOStatement::fetch(int $mode = PDO::FETCH_DEFAULT): mixed
+func stmtFetch(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	stmt, err := vm.pdoStatement(this)
+	if err != nil {
+		return nil, err
+	}
+	row, err := stmt.Fetch(intArg(args, 0, stdpdo.FETCH_DEFAULT))
+	if err != nil {
+		return vm.pdoFail(stmt.Conn(), err)
+	}
+	return row, nil
+}
+
+// PDOStatement::fetchAll(int $mode = PDO::FETCH_DEFAULT, mixed ...$args): array
+func stmtFetchAll(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	stmt, err := vm.pdoStatement(this)
+	if err != nil {
+		return nil, err
+	}
+	var arg *types.Value
+	if len(args) > 1 {
+		arg = args[1].Deref()
+	}
+	rows, err := stmt.FetchAll(intArg(args, 0, stdpdo.FETCH_DEFAULT), arg)
+	if err != nil {
+		return vm.pdoFail(stmt.Conn(), err)
+	}
+	return rows, nil
+}
+
+// PDOStatement::fetchColumn(int $column = 0): mixed
+func stmtFetchColumn(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	stmt, err := vm.pdoStatement(this)
+	if err != nil {
+		return nil, err
+	}
+	value, err := stmt.FetchColumn(intArg(args, 0, 0))
+	if err != nil {
+		return vm.pdoFail(stmt.Conn(), err)
+	}
+	return value, nil
+}
+
+// PDOStatement::setFetchMode(int $mode, mixed ...$args): bool
+func stmtSetFetchMode(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	stmt, err := vm.pdoStatement(this)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, vm.ThrowError("ArgumentCountError", "PDOStatement::setFetchMode() expects at least 1 argument, 0 given")
+	}
+	var arg *types.Value
+	if len(args) > 1 {
+		arg = args[1].Deref()
+	}
+	stmt.SetFetchMode(int(args[0].Deref().ToInt()), arg)
+	return types.NewBool(true), nil
+}
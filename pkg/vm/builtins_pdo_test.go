@@ -0,0 +1,50 @@
+package vm
+
+import (
+	"testing"
+
+	stdpdo "github.com/krizos/php-go/pkg/stdlib/pdo"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestPDO_ConstructWithoutDriverThrows(t *testing.T) {
+	vm := New()
+	obj := types.NewObjectFromClass(vm.classes["PDO"])
+
+	_, err := pdoConstruct(vm, obj, []*types.Value{types.NewString("sqlite::memory:")})
+	throwable, ok := err.(*ThrowableError)
+	if !ok || throwable.Object.ClassEntry.Name != "PDOException" {
+		t.Fatalf("Expected a PDOException, got %v", err)
+	}
+	if !vm.isInstanceOf(throwable.Object.ClassEntry, "RuntimeException") {
+		t.Error("Expected PDOException to extend RuntimeException")
+	}
+	if code := throwableProperty(throwable.Object, "code").ToString(); code != "IM001" {
+		t.Errorf("Expected code IM001, got %q", code)
+	}
+	if msg := throwableProperty(throwable.Object, "message").ToString(); msg != "SQLSTATE[IM001]: could not find driver" {
+		t.Errorf("Unexpected message %q", msg)
+	}
+
+	// Methods of an unconnected PDO object fail cleanly
+	if _, err := vm.CallCallable(callableArray(types.NewObject(obj), "exec"), []*types.Value{types.NewString("SELECT 1")}); err == nil {
+		t.Error("Expected exec() on an uninitialized PDO object to fail")
+	}
+}
+
+func TestPDO_ClassConstants(t *testing.T) {
+	vm := New()
+	constants := vm.classes["PDO"].Constants
+
+	if c := constants["FETCH_ASSOC"]; c == nil || c.Value.ToInt() != stdpdo.FETCH_ASSOC {
+		t.Errorf("Expected PDO::FETCH_ASSOC to be %d", stdpdo.FETCH_ASSOC)
+	}
+	if c := constants["ERRMODE_EXCEPTION"]; c == nil || c.Value.ToInt() != 2 {
+		t.Error("Expected PDO::ERRMODE_EXCEPTION to be 2")
+	}
+
+	drivers, err := vm.CallCallable(types.NewString("PDO::getAvailableDrivers"), nil)
+	if err != nil || !drivers.IsArray() {
+		t.Errorf("Expected PDO::getAvailableDrivers() to return an array, got %v", drivers)
+	}
+}
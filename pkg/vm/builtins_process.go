@@ -0,0 +1,154 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/stdlib/process"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Program Execution Builtins
+// ============================================================================
+
+// processFunction is a program execution builtin with its minimum argument
+// count; it gets the dereferenced arguments and the original ones, whose
+// references receive the output and exit status
+type processFunction struct {
+	required int
+	call     func(vm *VM, a, refs []*types.Value) (*types.Value, error)
+}
+
+// processFailure throws the errors of the process package and reports the
+// others as warnings
+func (vm *VM) processFailure(err error) (*types.Value, error) {
+	var thrown *process.Error
+	if errors.As(err, &thrown) {
+		return nil, vm.ThrowError(thrown.Class, "%s", thrown.Message)
+	}
+	vm.warning("%s", err)
+	return types.NewBool(false), nil
+}
+
+// processOutput passes the output of a command to the script's output
+func (vm *VM) processOutput(data string) {
+	vm.writeOutput([]byte(data))
+}
+
+// processFunctions maps the program execution functions to their
+// implementations
+var processFunctions = map[string]processFunction{
+	"exec": {1, func(vm *VM, a, refs []*types.Value) (*types.Value, error) {
+		lines, status, err := process.Exec(a[0].ToString())
+		if err != nil {
+			return vm.processFailure(err)
+		}
+		if len(refs) > 1 {
+			// Lines are appended to an array already in $output
+			output := types.NewEmptyArray()
+			if a[1].Type() == types.TypeArray {
+				output = a[1].ToArray().Copy()
+			}
+			for _, line := range lines {
+				output.Append(types.NewString(line))
+			}
+			assignRefArg(refs, 1, types.NewArray(output))
+		}
+		assignRefArg(refs, 2, types.NewInt(int64(status)))
+		if len(lines) == 0 {
+			return types.NewString(""), nil
+		}
+		return types.NewString(lines[len(lines)-1]), nil
+	}},
+	"shell_exec": {1, func(vm *VM, a, refs []*types.Value) (*types.Value, error) {
+		result, err := process.ShellExec(a[0].ToString())
+		if err != nil {
+			return vm.processFailure(err)
+		}
+		return result, nil
+	}},
+	"system": {1, func(vm *VM, a, refs []*types.Value) (*types.Value, error) {
+		last, status, err := process.System(a[0].ToString(), vm.processOutput)
+		if err != nil {
+			return vm.processFailure(err)
+		}
+		assignRefArg(refs, 1, types.NewInt(int64(status)))
+		return types.NewString(last), nil
+	}},
+	"passthru": {1, func(vm *VM, a, refs []*types.Value) (*types.Value, error) {
+		status, err := process.Passthru(a[0].ToString(), vm.processOutput)
+		if err != nil {
+			return vm.processFailure(err)
+		}
+		assignRefArg(refs, 1, types.NewInt(int64(status)))
+		return types.NewNull(), nil
+	}},
+	"escapeshellarg": {1, func(vm *VM, a, refs []*types.Value) (*types.Value, error) {
+		escaped, err := process.EscapeShellArg(a[0].ToString())
+		if err != nil {
+			return vm.processFailure(err)
+		}
+		return types.NewString(escaped), nil
+	}},
+	"escapeshellcmd": {1, func(vm *VM, a, refs []*types.Value) (*types.Value, error) {
+		escaped, err := process.EscapeShellCmd(a[0].ToString())
+		if err != nil {
+			return vm.processFailure(err)
+		}
+		return types.NewString(escaped), nil
+	}},
+	"popen": {2, func(vm *VM, a, refs []*types.Value) (*types.Value, error) {
+		stream, err := process.Popen(a[0].ToString(), a[1].ToString())
+		if err != nil {
+			return vm.processFailure(err)
+		}
+		return stream, nil
+	}},
+	"pclose": {1, func(vm *VM, a, refs []*types.Value) (*types.Value, error) {
+		return process.Pclose(a[0]), nil
+	}},
+	"proc_open": {3, func(vm *VM, a, refs []*types.Value) (*types.Value, error) {
+		if a[1].Type() != types.TypeArray {
+			return nil, vm.ThrowError("TypeError", "proc_open(): Argument #2 ($descriptor_spec) must be of type array, %s given", a[1].TypeName())
+		}
+		var opts process.ProcOptions
+		if len(a) > 3 && !a[3].IsNull() {
+			opts.Dir = a[3].ToString()
+		}
+		if len(a) > 4 && a[4].Type() == types.TypeArray {
+			opts.Env = a[4].ToArray()
+		}
+		proc, pipes, err := process.ProcOpen(a[0], a[1].ToArray(), opts)
+		if err != nil {
+			return vm.processFailure(err)
+		}
+		assignRefArg(refs, 2, types.NewArray(pipes))
+		return proc, nil
+	}},
+	"proc_close": {1, func(vm *VM, a, refs []*types.Value) (*types.Value, error) {
+		return process.ProcClose(a[0]), nil
+	}},
+	"proc_get_status": {1, func(vm *VM, a, refs []*types.Value) (*types.Value, error) {
+		return process.ProcGetStatus(a[0]), nil
+	}},
+	"proc_terminate": {1, func(vm *VM, a, refs []*types.Value) (*types.Value, error) {
+		signal := int64(15)
+		if len(a) > 1 {
+			signal = a[1].ToInt()
+		}
+		return process.ProcTerminate(a[0], signal), nil
+	}},
+}
+
+// registerProcessBuiltins registers the program execution functions
+func (vm *VM) registerProcessBuiltins() {
+	for name, fn := range processFunctions {
+		vm.RegisterBuiltin(name, func(vm *VM, args []*types.Value) (*types.Value, error) {
+			if len(args) < fn.required {
+				return nil, fmt.Errorf("%s() expects at least %d argument(s), %d given", name, fn.required, len(args))
+			}
+			return fn.call(vm, derefArgs(args), args)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestProcessBuiltins_Exec(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+
+	existing := types.NewEmptyArray()
+	existing.Append(types.NewString("kept"))
+	output, status := types.NewReference(types.NewArray(existing)), types.NewReference(types.NewNull())
+	last := call("exec", types.NewString("echo one; echo two; exit 1"), output, status)
+	if last.ToString() != "two" || status.Deref().ToInt() != 1 {
+		t.Errorf("exec() = %v, status %v", last, status)
+	}
+	if lines := output.Deref().ToArray(); lines.Len() != 3 {
+		t.Errorf("exec() appends to $output: %v", lines)
+	}
+
+	if last := call("system", types.NewString("echo shown"), status); last.ToString() != "shown" || vm.GetOutput() != "shown\n" {
+		t.Errorf("system() = %v, output %q", last, vm.GetOutput())
+	}
+	if arg := call("escapeshellarg", types.NewString("a'b")); arg.ToString() != `'a'\''b'` {
+		t.Errorf("escapeshellarg() = %v", arg)
+	}
+
+	_, err := vm.CallCallable(types.NewString("exec"), []*types.Value{types.NewString("")})
+	expectThrown(t, err, "ValueError", "exec(): Argument #1 ($command) cannot be empty")
+}
+
+func TestProcessBuiltins_ProcOpen(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+
+	pipe := func(mode string) *types.Value {
+		return types.NewArray(types.NewArrayFromMap(map[interface{}]*types.Value{0: types.NewString("pipe"), 1: types.NewString(mode)}))
+	}
+	spec := types.NewArrayFromMap(map[interface{}]*types.Value{0: pipe("r"), 1: pipe("w")})
+	pipes := types.NewReference(types.NewNull())
+	proc := call("proc_open", types.NewString("tr a-z A-Z"), types.NewArray(spec), pipes)
+	if !proc.IsResource() {
+		t.Fatalf("proc_open() = %v (%s)", proc, vm.GetOutput())
+	}
+	if kind := call("get_resource_type", proc); kind.ToString() != "process" {
+		t.Errorf("get_resource_type() = %v", kind)
+	}
+	stdin, _ := pipes.Deref().ToArray().Get(types.NewInt(0))
+	stdout, _ := pipes.Deref().ToArray().Get(types.NewInt(1))
+	call("fwrite", stdin, types.NewString("shout"))
+	call("fclose", stdin)
+	if result := call("stream_get_contents", stdout); result.ToString() != "SHOUT" {
+		t.Errorf("stream_get_contents() = %v", result)
+	}
+	call("fclose", stdout)
+	if code := call("proc_close", proc); code.ToInt() != 0 {
+		t.Errorf("proc_close() = %v", code)
+	}
+
+	handle := call("popen", types.NewString("echo piped"), types.NewString("r"))
+	if line := call("fgets", handle); line.ToString() != "piped\n" {
+		t.Errorf("fgets() = %v", line)
+	}
+	if code := call("pclose", handle); code.ToInt() != 0 {
+		t.Errorf("pclose() = %v", code)
+	}
+}
@@ -0,0 +1,1190 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/stdlib/reflection"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Attributes and Reflection
+// ============================================================================
+
+// Attribute targets and flags (Attribute::TARGET_* and
+// Attribute::IS_REPEATABLE)
+const (
+	attributeTargetClass = 1 << iota
+	attributeTargetFunction
+	attributeTargetMethod
+	attributeTargetProperty
+	attributeTargetClassConstant
+	attributeTargetParameter
+	attributeIsRepeatable
+
+	attributeTargetAll = attributeIsRepeatable - 1
+)
+
+// reflectionAttributeIsInstanceof is ReflectionAttribute::IS_INSTANCEOF,
+// the getAttributes() flag matching attributes by class hierarchy
+const reflectionAttributeIsInstanceof = 2
+
+// attributeTargetNames names the attribute targets in flag order
+var attributeTargetNames = []string{"class", "function", "method", "property", "class constant", "parameter"}
+
+// builtinAttributes are the attribute classes declared by the engine, with
+// the targets they allow
+var builtinAttributes = []struct {
+	name    string
+	targets int64
+}{
+	{"ReturnTypeWillChange", attributeTargetMethod},
+	{"AllowDynamicProperties", attributeTargetClass},
+	{"SensitiveParameter", attributeTargetParameter},
+	{"Override", attributeTargetMethod},
+}
+
+// reflectedAttribute is the state of a ReflectionAttribute object
+type reflectedAttribute struct {
+	attr     *types.Attribute
+	target   int64             // Attribute::TARGET_* of the declaration
+	repeated bool              // The attribute appears more than once there
+	scope    *types.ClassEntry // Class resolving self:: in the arguments
+}
+
+// registerReflectionBuiltins registers the Attribute class, the attributes
+// declared by the engine and the Reflection API
+func (vm *VM) registerReflectionBuiltins() {
+	vm.classes["Attribute"] = attributeClass()
+	for _, def := range builtinAttributes {
+		class := types.NewClassEntry(def.name)
+		class.IsFinal = true
+		class.Attributes = []*types.Attribute{attributeOf("Attribute", types.NewInt(def.targets))}
+		vm.classes[class.Name] = class
+	}
+
+	reflector := types.NewInterfaceEntry("Reflector")
+	vm.classes[reflector.Name] = interfaceClass(reflector)
+
+	vm.classes["Reflection"] = reflectionClass()
+	vm.classes["ReflectionClass"] = reflectionClassClass(reflector)
+	object := types.NewClassEntry("ReflectionObject")
+	object.InheritFrom(vm.classes["ReflectionClass"])
+	object.Constructor = object.ParentClass.Constructor
+	vm.classes[object.Name] = object
+	vm.classes["ReflectionClassConstant"] = reflectionClassConstantClass(reflector)
+	vm.classes["ReflectionProperty"] = reflectionPropertyClass(reflector)
+	vm.registerReflectionFunctionClasses(reflector)
+	vm.classes["ReflectionAttribute"] = reflectionAttributeClass(reflector)
+}
+
+// attributeClass builds the Attribute class, which marks the classes
+// usable as attributes and the declarations they may target
+func attributeClass() *types.ClassEntry {
+	class := types.NewClassEntry("Attribute")
+	class.IsFinal = true
+	class.Attributes = []*types.Attribute{attributeOf("Attribute", types.NewInt(attributeTargetClass))}
+
+	for i, name := range []string{"CLASS", "FUNCTION", "METHOD", "PROPERTY", "CLASS_CONSTANT", "PARAMETER"} {
+		class.Constants["TARGET_"+name] = &types.ClassConstant{
+			Name: "TARGET_" + name, Value: types.NewInt(1 << i), Visibility: types.VisibilityPublic,
+		}
+	}
+	class.Constants["TARGET_ALL"] = &types.ClassConstant{Name: "TARGET_ALL", Value: types.NewInt(attributeTargetAll), Visibility: types.VisibilityPublic}
+	class.Constants["IS_REPEATABLE"] = &types.ClassConstant{Name: "IS_REPEATABLE", Value: types.NewInt(attributeIsRepeatable), Visibility: types.VisibilityPublic}
+
+	class.Properties["flags"] = &types.PropertyDef{
+		Name: "flags", Visibility: types.VisibilityPublic, Type: "int",
+		HasDefault: true, Default: types.NewInt(attributeTargetAll), DeclaringClass: class.Name,
+	}
+	addNativeMethod(class, "__construct", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		flags := types.NewInt(attributeTargetAll)
+		if len(args) > 0 && args[0] != nil {
+			flags = types.NewInt(args[0].Deref().ToInt())
+		}
+		this.AddProperty("flags", &types.Property{Value: flags, Visibility: types.VisibilityPublic})
+		return nil, nil
+	})
+	class.Constructor = class.Methods["__construct"]
+	class.Constructor.IsConstructor = true
+	class.Constructor.Parameters = []*types.ParameterDef{{Name: "flags", HasDefault: true, Default: types.NewInt(attributeTargetAll)}}
+	return class
+}
+
+// attributeOf builds an attribute with positional arguments
+func attributeOf(name string, args ...*types.Value) *types.Attribute {
+	attr := &types.Attribute{Name: name}
+	for _, arg := range args {
+		attr.Arguments = append(attr.Arguments, types.AttributeArgument{Value: arg})
+	}
+	return attr
+}
+
+// reflectionAttributeClass builds ReflectionAttribute, whose objects are
+// only created by the getAttributes() methods
+func reflectionAttributeClass(reflector *types.InterfaceEntry) *types.ClassEntry {
+	class := types.NewClassEntry("ReflectionAttribute")
+	class.IsFinal = true
+	class.Interfaces = append(class.Interfaces, reflector)
+	class.Constants["IS_INSTANCEOF"] = &types.ClassConstant{
+		Name: "IS_INSTANCEOF", Value: types.NewInt(reflectionAttributeIsInstanceof), Visibility: types.VisibilityPublic,
+	}
+
+	addNativeMethod(class, "getName", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		reflected, err := vm.reflectedAttributeOf(this)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewString(reflected.attr.Name), nil
+	})
+	addNativeMethod(class, "getArguments", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		reflected, err := vm.reflectedAttributeOf(this)
+		if err != nil {
+			return nil, err
+		}
+		params, err := vm.attributeArguments(reflected)
+		if err != nil {
+			return nil, err
+		}
+		arguments := types.NewEmptyArray()
+		for _, arg := range params.params {
+			arguments.Append(arg)
+		}
+		for _, arg := range params.named {
+			arguments.Set(types.NewString(arg.name), arg.value)
+		}
+		return types.NewArray(arguments), nil
+	})
+	addNativeMethod(class, "getTarget", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		reflected, err := vm.reflectedAttributeOf(this)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewInt(reflected.target), nil
+	})
+	addNativeMethod(class, "isRepeated", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		reflected, err := vm.reflectedAttributeOf(this)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewBool(reflected.repeated), nil
+	})
+	addNativeMethod(class, "newInstance", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		reflected, err := vm.reflectedAttributeOf(this)
+		if err != nil {
+			return nil, err
+		}
+		return vm.newAttributeInstance(reflected)
+	})
+	return class
+}
+
+// reflectedAttributeOf returns the state of a ReflectionAttribute object
+func (vm *VM) reflectedAttributeOf(obj *types.Object) (*reflectedAttribute, error) {
+	reflected, ok := obj.Internal.(*reflectedAttribute)
+	if !ok {
+		return nil, vm.ThrowError("Error", "Internal error: Failed to retrieve the reflection object")
+	}
+	return reflected, nil
+}
+
+// reflectAttributes implements the getAttributes(?string $name = null,
+// int $flags = 0) methods: it returns ReflectionAttribute objects for the
+// attributes of a declaration, optionally filtered by class name, or with
+// ReflectionAttribute::IS_INSTANCEOF by class hierarchy
+func (vm *VM) reflectAttributes(method string, attrs []*types.Attribute, target int64, scope *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+	args = derefArgs(args)
+	var flags int64
+	if len(args) > 1 {
+		flags = args[1].ToInt()
+	}
+	if flags&^reflectionAttributeIsInstanceof != 0 {
+		return nil, vm.ThrowError("ValueError", "%s(): Argument #2 ($flags) must be a valid attribute filter flag", method)
+	}
+
+	var filter string
+	var filterClass *types.ClassEntry
+	if len(args) > 0 && !args[0].IsNull() {
+		filter = strings.TrimPrefix(args[0].ToString(), "\\")
+		if flags&reflectionAttributeIsInstanceof != 0 {
+			class, exists, err := vm.loadClass(filter)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				return nil, vm.ThrowError("Error", "Class \"%s\" not found", filter)
+			}
+			filterClass = class
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, attr := range attrs {
+		counts[strings.ToLower(attr.Name)]++
+	}
+
+	result := types.NewEmptyArray()
+	for _, attr := range attrs {
+		if filterClass != nil {
+			class, exists, err := vm.loadClass(attr.Name)
+			if err != nil {
+				return nil, err
+			}
+			if !exists || !vm.isInstanceOf(class, filterClass.Name) {
+				continue
+			}
+		} else if filter != "" && !strings.EqualFold(attr.Name, filter) {
+			continue
+		}
+
+		obj := types.NewObjectFromClass(vm.classes["ReflectionAttribute"])
+		obj.Internal = &reflectedAttribute{
+			attr:     attr,
+			target:   target,
+			repeated: counts[strings.ToLower(attr.Name)] > 1,
+			scope:    scope,
+		}
+		result.Append(types.NewObject(obj))
+	}
+	return types.NewArray(result), nil
+}
+
+// attributeArguments evaluates the arguments of an attribute, positional
+// and named, as the arguments of a constructor call
+func (vm *VM) attributeArguments(reflected *reflectedAttribute) (*CallParams, error) {
+	params := &CallParams{}
+	for _, arg := range reflected.attr.Arguments {
+		value := arg.Value
+		if arg.Expr != nil {
+			var err error
+			if value, err = vm.evalConstantExpr(arg.Expr, reflected.scope); err != nil {
+				return nil, err
+			}
+		}
+		value = assignValue(value)
+		if arg.Name == "" {
+			params.params = append(params.params, value)
+		} else if err := vm.addNamedArgument(params, arg.Name, value); err != nil {
+			return nil, err
+		}
+	}
+	return params, nil
+}
+
+// newAttributeInstance instantiates the class of an attribute with its
+// arguments, once checked that the class is an attribute allowed on the
+// declaration
+func (vm *VM) newAttributeInstance(reflected *reflectedAttribute) (*types.Value, error) {
+	name := reflected.attr.Name
+	class, exists, err := vm.loadClass(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, vm.ThrowError("Error", "Attribute class \"%s\" not found", name)
+	}
+
+	flags, ok, err := vm.attributeFlags(class)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, vm.ThrowError("Error", "Attempting to use non-attribute class \"%s\" as attribute", class.Name)
+	}
+	if flags&reflected.target == 0 {
+		var allowed []string
+		for i, target := range attributeTargetNames {
+			if flags&(1<<i) != 0 {
+				allowed = append(allowed, target)
+			}
+		}
+		return nil, vm.ThrowError("Error", "Attribute \"%s\" cannot target %s (allowed targets: %s)",
+			class.Name, attributeTargetName(reflected.target), strings.Join(allowed, ", "))
+	}
+	if reflected.repeated && flags&attributeIsRepeatable == 0 {
+		return nil, vm.ThrowError("Error", "Attribute \"%s\" must not be repeated", class.Name)
+	}
+
+	params, err := vm.attributeArguments(reflected)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := vm.instantiate(class, params)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewObject(obj), nil
+}
+
+// attributeFlags returns the flags of the #[Attribute] declaration marking
+// a class as an attribute; ok is false for classes without one
+func (vm *VM) attributeFlags(class *types.ClassEntry) (flags int64, ok bool, err error) {
+	for _, attr := range class.Attributes {
+		if !strings.EqualFold(attr.Name, "Attribute") {
+			continue
+		}
+		params, err := vm.attributeArguments(&reflectedAttribute{attr: attr, scope: class})
+		if err != nil {
+			return 0, false, err
+		}
+		flags = attributeTargetAll
+		if len(params.params) > 0 {
+			flags = params.params[0].ToInt()
+		}
+		for _, arg := range params.named {
+			if arg.name == "flags" {
+				flags = arg.value.ToInt()
+			}
+		}
+		return flags, true, nil
+	}
+	return 0, false, nil
+}
+
+// attributeTargetName names an attribute target flag
+func attributeTargetName(target int64) string {
+	for i, name := range attributeTargetNames {
+		if target == 1<<i {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// evalConstantExpr evaluates a constant expression whose class constant
+// references are resolved at run time, self:: and static:: against scope
+func (vm *VM) evalConstantExpr(expr *types.ConstantExpr, scope *types.ClassEntry) (*types.Value, error) {
+	if expr.Value != nil {
+		return expr.Value, nil
+	}
+
+	if expr.Operator != "" {
+		left, err := vm.evalConstantExpr(expr.Left, scope)
+		if err != nil {
+			return nil, err
+		}
+		right, err := vm.evalConstantExpr(expr.Right, scope)
+		if err != nil {
+			return nil, err
+		}
+		for op, operator := range binaryOperators {
+			if operator == expr.Operator {
+				return vm.binaryOp(op, left, right)
+			}
+		}
+		return nil, fmt.Errorf("unsupported operator %s in constant expression", expr.Operator)
+	}
+
+	class, err := vm.staticClass(&Frame{currentClass: scope}, expr.Class)
+	if err != nil {
+		return nil, err
+	}
+	if class == nil {
+		return nil, vm.ThrowError("Error", "Class \"%s\" not found", strings.TrimPrefix(expr.Class, "\\"))
+	}
+	value, exists := class.GetStaticConstant(expr.Constant, false, nil)
+	if !exists {
+		return nil, vm.ThrowError("Error", "Undefined constant %s::%s", class.Name, expr.Constant)
+	}
+	return value, nil
+}
+
+// ============================================================================
+// Reflection Classes
+// ============================================================================
+
+// reflectedConstant is the state of a ReflectionClassConstant object
+type reflectedConstant struct {
+	class    *types.ClassEntry // Class declaring the constant
+	constant *types.ClassConstant
+}
+
+// reflectedProperty is the state of a ReflectionProperty object; prop is
+// nil for dynamic properties
+type reflectedProperty struct {
+	class *types.ClassEntry // Class declaring the property
+	prop  *types.PropertyDef
+	name  string
+}
+
+// reflectionClass builds the Reflection class
+func reflectionClass() *types.ClassEntry {
+	class := types.NewClassEntry("Reflection")
+	addNativeMethod(class, "getModifierNames", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		if len(args) == 0 {
+			return nil, vm.ThrowError("ArgumentCountError", "Reflection::getModifierNames() expects exactly 1 argument, 0 given")
+		}
+		return stringList(reflection.ModifierNames(args[0].Deref().ToInt())), nil
+	}).IsStatic = true
+	return class
+}
+
+// addReflectionNameProperties declares the public properties a reflection
+// class exposes the reflected names in
+func addReflectionNameProperties(class *types.ClassEntry, names ...string) {
+	for _, name := range names {
+		class.Properties[name] = &types.PropertyDef{
+			Name: name, Visibility: types.VisibilityPublic, Type: "string", IsReadOnly: true,
+			DeclaringClass: class.Name,
+		}
+	}
+}
+
+// setReflectionNames initializes the name properties of a reflection object
+func setReflectionNames(obj *types.Object, names ...string) {
+	for i := 0; i+1 < len(names); i += 2 {
+		obj.AddProperty(names[i], &types.Property{Value: types.NewString(names[i+1]), Visibility: types.VisibilityPublic})
+	}
+}
+
+// addModifierConstants declares the IS_* constants of a reflection class
+func addModifierConstants(class *types.ClassEntry, constants map[string]int64) {
+	for name, value := range constants {
+		class.Constants[name] = &types.ClassConstant{Name: name, Value: types.NewInt(value), Visibility: types.VisibilityPublic}
+	}
+}
+
+// addClassReflector adds a method of ReflectionClass
+func addClassReflector(class *types.ClassEntry, name string, numParams int, fn func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error)) *types.MethodDef {
+	return addNativeMethod(class, name, numParams, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		reflected, err := vm.reflectedClass(this)
+		if err != nil {
+			return nil, err
+		}
+		return fn(vm, reflected, derefArgs(args))
+	})
+}
+
+// reflectionClassClass builds ReflectionClass
+func reflectionClassClass(reflector *types.InterfaceEntry) *types.ClassEntry {
+	class := types.NewClassEntry("ReflectionClass")
+	class.Interfaces = append(class.Interfaces, reflector)
+	addReflectionNameProperties(class, "name")
+	addModifierConstants(class, map[string]int64{
+		"IS_IMPLICIT_ABSTRACT": reflection.IS_IMPLICIT_ABSTRACT,
+		"IS_EXPLICIT_ABSTRACT": reflection.IS_EXPLICIT_ABSTRACT,
+		"IS_FINAL":             reflection.IS_FINAL,
+		"IS_READONLY":          reflection.IS_READONLY_CLASS,
+	})
+	addNativeMethod(class, "__construct", 1, reflectionClassConstruct)
+	class.Constructor = class.Methods["__construct"]
+	class.Constructor.IsConstructor = true
+
+	predicates := map[string]func(c *types.ClassEntry) bool{
+		"isInterface":    func(c *types.ClassEntry) bool { return c.IsInterface },
+		"isTrait":        func(c *types.ClassEntry) bool { return c.IsTrait },
+		"isEnum":         func(c *types.ClassEntry) bool { return c.IsEnum },
+		"isFinal":        func(c *types.ClassEntry) bool { return c.IsFinal },
+		"isReadOnly":     func(c *types.ClassEntry) bool { return c.IsReadOnly },
+		"inNamespace":    func(c *types.ClassEntry) bool { return strings.Contains(c.Name, "\\") },
+		"isCloneable":    func(c *types.ClassEntry) bool { return c.IsInstantiable() && !c.IsEnum },
+		"isAbstract":     func(c *types.ClassEntry) bool { return c.IsAbstract || c.HasAbstractMethods() },
+		"isInstantiable": func(c *types.ClassEntry) bool { return classInstantiable(c) },
+	}
+	for name, predicate := range predicates {
+		predicate := predicate
+		addClassReflector(class, name, 0, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+			return types.NewBool(predicate(reflected)), nil
+		})
+	}
+
+	addClassReflector(class, "getName", 0, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		return types.NewString(reflected.Name), nil
+	})
+	addClassReflector(class, "getShortName", 0, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		return types.NewString(lastNameSegment(reflected.Name)), nil
+	})
+	addClassReflector(class, "getNamespaceName", 0, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		return types.NewString(namespaceOf(reflected.Name)), nil
+	})
+	addClassReflector(class, "getModifiers", 0, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		return types.NewInt(reflection.ClassModifiers(reflected)), nil
+	})
+	addClassReflector(class, "getDocComment", 0, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		return docComment(reflected.DocComment), nil
+	})
+	addClassReflector(class, "getFileName", 0, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		if reflected.FileName == "" {
+			return types.NewBool(false), nil
+		}
+		return types.NewString(reflected.FileName), nil
+	})
+	addClassReflector(class, "getAttributes", 2, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		return vm.reflectAttributes("ReflectionClass::getAttributes", reflected.Attributes, attributeTargetClass, reflected, args)
+	})
+
+	// Hierarchy
+	addClassReflector(class, "getParentClass", 0, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		if reflected.ParentClass == nil {
+			return types.NewBool(false), nil
+		}
+		return vm.newReflectionClass(reflected.ParentClass), nil
+	})
+	addClassReflector(class, "getInterfaceNames", 0, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		return stringList(classInterfaceNames(reflected)), nil
+	})
+	addClassReflector(class, "getTraitNames", 0, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		return stringList(reflected.GetTraitNames()), nil
+	})
+	addClassReflector(class, "implementsInterface", 1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		iface, err := vm.reflectionClassArg("ReflectionClass::implementsInterface", args, "Interface")
+		if err != nil {
+			return nil, err
+		}
+		if !iface.IsInterface {
+			return nil, vm.ThrowError("ReflectionException", "%s is not an interface", iface.Name)
+		}
+		return types.NewBool(vm.isInstanceOf(reflected, iface.Name)), nil
+	})
+	addClassReflector(class, "isSubclassOf", 1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		parent, err := vm.reflectionClassArg("ReflectionClass::isSubclassOf", args, "Class")
+		if err != nil {
+			return nil, err
+		}
+		return types.NewBool(parent != reflected && vm.isInstanceOf(reflected, parent.Name)), nil
+	})
+	addClassReflector(class, "isInstance", 1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		if len(args) == 0 || args[0].Type() != types.TypeObject {
+			return nil, vm.ThrowError("TypeError", "ReflectionClass::isInstance(): Argument #1 ($object) must be of type object")
+		}
+		return types.NewBool(vm.isInstanceOf(args[0].ToObject().ClassEntry, reflected.Name)), nil
+	})
+
+	// Methods
+	addClassReflector(class, "hasMethod", 1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		_, ok := reflection.FindMethod(reflected, reflectionStringArg(args, 0))
+		return types.NewBool(ok), nil
+	})
+	addClassReflector(class, "getMethod", 1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		name := reflectionStringArg(args, 0)
+		method, ok := reflection.FindMethod(reflected, name)
+		if !ok {
+			return nil, vm.ThrowError("ReflectionException", "Method %s::%s() does not exist", reflected.Name, name)
+		}
+		return vm.newReflectionMethod(reflected, method), nil
+	})
+	addClassReflector(class, "getMethods", 1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		filter := modifierFilter(args)
+		methods := types.NewEmptyArray()
+		for _, method := range reflection.Methods(reflected) {
+			if filter == 0 || reflection.MethodModifiers(method)&filter != 0 {
+				methods.Append(vm.newReflectionMethod(reflected, method))
+			}
+		}
+		return types.NewArray(methods), nil
+	})
+	addClassReflector(class, "getConstructor", 0, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		if reflected.Constructor == nil {
+			return types.NewNull(), nil
+		}
+		return vm.newReflectionMethod(reflected, reflected.Constructor), nil
+	})
+
+	// Properties
+	addClassReflector(class, "hasProperty", 1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		_, ok := reflection.FindProperty(reflected, reflectionStringArg(args, 0))
+		return types.NewBool(ok), nil
+	})
+	addClassReflector(class, "getProperty", 1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		name := reflectionStringArg(args, 0)
+		prop, ok := reflection.FindProperty(reflected, name)
+		if !ok {
+			return nil, vm.ThrowError("ReflectionException", "Property %s::$%s does not exist", reflected.Name, name)
+		}
+		return vm.newReflectionProperty(reflected, prop, name), nil
+	})
+	addClassReflector(class, "getProperties", 1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		filter := modifierFilter(args)
+		props := types.NewEmptyArray()
+		for _, prop := range reflection.Properties(reflected) {
+			if filter == 0 || reflection.PropertyModifiers(prop)&filter != 0 {
+				props.Append(vm.newReflectionProperty(reflected, prop, prop.Name))
+			}
+		}
+		return types.NewArray(props), nil
+	})
+	addClassReflector(class, "getDefaultProperties", 0, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		defaults := types.NewEmptyArray()
+		for _, prop := range reflection.Properties(reflected) {
+			if prop.IsStatic {
+				if value, ok := reflected.GetStaticProperty(prop.Name); ok {
+					defaults.Set(types.NewString(prop.Name), value.Copy())
+				}
+			} else if prop.Default != nil {
+				defaults.Set(types.NewString(prop.Name), prop.Default.Copy())
+			}
+		}
+		return types.NewArray(defaults), nil
+	})
+	addClassReflector(class, "getStaticPropertyValue", 2, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		name := reflectionStringArg(args, 0)
+		if prop, ok := reflected.Properties[name]; ok && prop.IsStatic {
+			if value, ok := reflected.GetStaticProperty(name); ok {
+				return value.Deref(), nil
+			}
+		}
+		if len(args) > 1 {
+			return args[1], nil
+		}
+		return nil, vm.ThrowError("ReflectionException", "Property %s::$%s does not exist", reflected.Name, name)
+	})
+	addClassReflector(class, "setStaticPropertyValue", 2, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		name := reflectionStringArg(args, 0)
+		if len(args) < 2 || !reflected.SetStaticProperty(name, assignValue(args[1])) {
+			return nil, vm.ThrowError("ReflectionException", "Class %s does not have a property named %s", reflected.Name, name)
+		}
+		return nil, nil
+	})
+	addClassReflector(class, "getStaticProperties", 0, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		props := types.NewEmptyArray()
+		for _, prop := range reflection.Properties(reflected) {
+			if value, ok := reflected.GetStaticProperty(prop.Name); ok && prop.IsStatic {
+				props.Set(types.NewString(prop.Name), value.Deref())
+			}
+		}
+		return types.NewArray(props), nil
+	})
+
+	// Constants
+	addClassReflector(class, "hasConstant", 1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		_, _, ok := findClassConstant(reflected, reflectionStringArg(args, 0))
+		return types.NewBool(ok), nil
+	})
+	addClassReflector(class, "getConstant", 1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		constant, _, ok := findClassConstant(reflected, reflectionStringArg(args, 0))
+		if !ok {
+			return types.NewBool(false), nil
+		}
+		return constant.Value, nil
+	})
+	addClassReflector(class, "getConstants", 1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		filter := modifierFilter(args)
+		constants := types.NewEmptyArray()
+		for _, constant := range reflection.Constants(reflected) {
+			if filter == 0 || reflection.ConstantModifiers(constant)&filter != 0 {
+				constants.Set(types.NewString(constant.Name), constant.Value)
+			}
+		}
+		return types.NewArray(constants), nil
+	})
+	addClassReflector(class, "getReflectionConstant", 1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		constant, declaring, ok := findClassConstant(reflected, reflectionStringArg(args, 0))
+		if !ok {
+			return types.NewBool(false), nil
+		}
+		return vm.newReflectionConstant(declaring, constant), nil
+	})
+	addClassReflector(class, "getReflectionConstants", 1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		filter := modifierFilter(args)
+		constants := types.NewEmptyArray()
+		for _, constant := range reflection.Constants(reflected) {
+			if filter == 0 || reflection.ConstantModifiers(constant)&filter != 0 {
+				_, declaring, _ := findClassConstant(reflected, constant.Name)
+				constants.Append(vm.newReflectionConstant(declaring, constant))
+			}
+		}
+		return types.NewArray(constants), nil
+	})
+
+	// Instantiation
+	addClassReflector(class, "newInstance", -1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		return vm.newReflectedInstance(reflected, &CallParams{params: args})
+	})
+	addClassReflector(class, "newInstanceArgs", 1, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		params := &CallParams{}
+		if len(args) > 0 {
+			if err := vm.unpackArguments(params, args[0]); err != nil {
+				return nil, err
+			}
+		}
+		return vm.newReflectedInstance(reflected, params)
+	})
+	addClassReflector(class, "newInstanceWithoutConstructor", 0, func(vm *VM, reflected *types.ClassEntry, args []*types.Value) (*types.Value, error) {
+		obj, err := vm.newInstance(reflected)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewObject(obj), nil
+	})
+	return class
+}
+
+// ReflectionClass::__construct(object|string $objectOrClass)
+func reflectionClassConstruct(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	if len(args) == 0 {
+		return nil, vm.ThrowError("ArgumentCountError", "ReflectionClass::__construct() expects exactly 1 argument, 0 given")
+	}
+	arg := args[0].Deref()
+	if arg.Type() == types.TypeObject {
+		this.Internal = arg.ToObject().ClassEntry
+	} else {
+		class, exists, err := vm.loadClass(arg.ToString())
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, vm.ThrowError("ReflectionException", "Class \"%s\" does not exist", strings.TrimPrefix(arg.ToString(), "\\"))
+		}
+		this.Internal = class
+	}
+	setReflectionNames(this, "name", this.Internal.(*types.ClassEntry).Name)
+	return nil, nil
+}
+
+// newReflectionClass creates a ReflectionClass object
+func (vm *VM) newReflectionClass(class *types.ClassEntry) *types.Value {
+	obj := types.NewObjectFromClass(vm.classes["ReflectionClass"])
+	obj.Internal = class
+	setReflectionNames(obj, "name", class.Name)
+	return types.NewObject(obj)
+}
+
+// reflectedClass returns the class a ReflectionClass object reflects
+func (vm *VM) reflectedClass(obj *types.Object) (*types.ClassEntry, error) {
+	class, ok := obj.Internal.(*types.ClassEntry)
+	if !ok {
+		return nil, vm.ThrowError("Error", "Internal error: Failed to retrieve the reflection object")
+	}
+	return class, nil
+}
+
+// reflectionClassArg loads the class named by the first argument of a
+// ReflectionClass method, which may also be a ReflectionClass
+func (vm *VM) reflectionClassArg(method string, args []*types.Value, kind string) (*types.ClassEntry, error) {
+	if len(args) == 0 {
+		return nil, vm.ThrowError("ArgumentCountError", "%s() expects exactly 1 argument, 0 given", method)
+	}
+	if args[0].Type() == types.TypeObject {
+		return vm.reflectedClass(args[0].ToObject())
+	}
+	name := strings.TrimPrefix(args[0].ToString(), "\\")
+	class, exists, err := vm.loadClass(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, vm.ThrowError("ReflectionException", "%s \"%s\" does not exist", kind, name)
+	}
+	return class, nil
+}
+
+// newReflectedInstance implements ReflectionClass::newInstance() and
+// newInstanceArgs()
+func (vm *VM) newReflectedInstance(class *types.ClassEntry, params *CallParams) (*types.Value, error) {
+	if class.Constructor == nil && (len(params.params) > 0 || len(params.named) > 0) {
+		return nil, vm.ThrowError("ReflectionException",
+			"Class %s does not have a constructor, so you cannot pass any constructor arguments", class.Name)
+	}
+	if class.Constructor != nil && class.Constructor.Visibility != types.VisibilityPublic {
+		return nil, vm.ThrowError("ReflectionException", "Access to non-public constructor of class %s", class.Name)
+	}
+	obj, err := vm.instantiate(class, params)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewObject(obj), nil
+}
+
+// classInstantiable reports whether new can create objects of a class
+func classInstantiable(class *types.ClassEntry) bool {
+	if !class.IsInstantiable() || class.IsEnum {
+		return false
+	}
+	return class.Constructor == nil || class.Constructor.Visibility == types.VisibilityPublic
+}
+
+// classInterfaceNames returns the names of the interfaces a class
+// implements, including the ones of its ancestors and parent interfaces
+func classInterfaceNames(class *types.ClassEntry) []string {
+	var names []string
+	seen := make(map[string]bool)
+	var add func(iface *types.InterfaceEntry)
+	add = func(iface *types.InterfaceEntry) {
+		if seen[iface.Name] {
+			return
+		}
+		seen[iface.Name] = true
+		names = append(names, iface.Name)
+		for _, parent := range iface.ParentInterfaces {
+			add(parent)
+		}
+	}
+	for current := class; current != nil; current = current.ParentClass {
+		for _, iface := range current.Interfaces {
+			add(iface)
+		}
+	}
+	return names
+}
+
+// findClassConstant looks up a constant of a class or its ancestors, and
+// the class declaring it
+func findClassConstant(class *types.ClassEntry, name string) (*types.ClassConstant, *types.ClassEntry, bool) {
+	for current := class; current != nil; current = current.ParentClass {
+		if constant, ok := current.Constants[name]; ok {
+			return constant, current, true
+		}
+	}
+	return nil, nil, false
+}
+
+// modifierFilter returns the ?int $filter argument of the methods listing
+// members (0 for no filter)
+func modifierFilter(args []*types.Value) int64 {
+	if len(args) == 0 || args[0].IsNull() {
+		return 0
+	}
+	return args[0].ToInt()
+}
+
+// reflectionStringArg returns a string argument, empty when missing
+func reflectionStringArg(args []*types.Value, i int) string {
+	if i >= len(args) {
+		return ""
+	}
+	return args[i].ToString()
+}
+
+// docComment returns the doc comment of a declaration, false when it has
+// none
+func docComment(comment string) *types.Value {
+	if comment == "" {
+		return types.NewBool(false)
+	}
+	return types.NewString(comment)
+}
+
+// stringList converts a list of strings into a PHP list
+func stringList(values []string) *types.Value {
+	list := types.NewEmptyArray()
+	for _, value := range values {
+		list.Append(types.NewString(value))
+	}
+	return types.NewArray(list)
+}
+
+// lastNameSegment returns the name without its namespace
+func lastNameSegment(name string) string {
+	return name[strings.LastIndex(name, "\\")+1:]
+}
+
+// namespaceOf returns the namespace of a qualified name
+func namespaceOf(name string) string {
+	if i := strings.LastIndex(name, "\\"); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// ============================================================================
+// ReflectionClassConstant
+// ============================================================================
+
+// addConstantReflector adds a method of ReflectionClassConstant
+func addConstantReflector(class *types.ClassEntry, name string, numParams int, fn func(vm *VM, reflected *reflectedConstant, args []*types.Value) (*types.Value, error)) *types.MethodDef {
+	return addNativeMethod(class, name, numParams, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		reflected, ok := this.Internal.(*reflectedConstant)
+		if !ok {
+			return nil, vm.ThrowError("Error", "Internal error: Failed to retrieve the reflection object")
+		}
+		return fn(vm, reflected, derefArgs(args))
+	})
+}
+
+// reflectionClassConstantClass builds ReflectionClassConstant
+func reflectionClassConstantClass(reflector *types.InterfaceEntry) *types.ClassEntry {
+	class := types.NewClassEntry("ReflectionClassConstant")
+	class.Interfaces = append(class.Interfaces, reflector)
+	addReflectionNameProperties(class, "name", "class")
+	addModifierConstants(class, map[string]int64{
+		"IS_PUBLIC":    reflection.IS_PUBLIC,
+		"IS_PROTECTED": reflection.IS_PROTECTED,
+		"IS_PRIVATE":   reflection.IS_PRIVATE,
+		"IS_FINAL":     reflection.IS_FINAL,
+	})
+
+	addNativeMethod(class, "__construct", 2, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		owner, err := vm.reflectionClassArg("ReflectionClassConstant::__construct", derefArgs(args), "Class")
+		if err != nil {
+			return nil, err
+		}
+		name := reflectionStringArg(derefArgs(args), 1)
+		constant, declaring, ok := findClassConstant(owner, name)
+		if !ok {
+			return nil, vm.ThrowError("ReflectionException", "Constant %s::%s does not exist", owner.Name, name)
+		}
+		this.Internal = &reflectedConstant{class: declaring, constant: constant}
+		setReflectionNames(this, "name", constant.Name, "class", declaring.Name)
+		return nil, nil
+	})
+	class.Constructor = class.Methods["__construct"]
+	class.Constructor.IsConstructor = true
+
+	visibilities := map[string]types.PropertyVisibility{
+		"isPublic":    types.VisibilityPublic,
+		"isProtected": types.VisibilityProtected,
+		"isPrivate":   types.VisibilityPrivate,
+	}
+	for name, visibility := range visibilities {
+		visibility := visibility
+		addConstantReflector(class, name, 0, func(vm *VM, reflected *reflectedConstant, args []*types.Value) (*types.Value, error) {
+			return types.NewBool(reflected.constant.Visibility == visibility), nil
+		})
+	}
+	addConstantReflector(class, "isFinal", 0, func(vm *VM, reflected *reflectedConstant, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.constant.IsFinal), nil
+	})
+	addConstantReflector(class, "isEnumCase", 0, func(vm *VM, reflected *reflectedConstant, args []*types.Value) (*types.Value, error) {
+		_, isCase := reflected.class.EnumCases[reflected.constant.Name]
+		return types.NewBool(reflected.class.IsEnum && isCase), nil
+	})
+	addConstantReflector(class, "getName", 0, func(vm *VM, reflected *reflectedConstant, args []*types.Value) (*types.Value, error) {
+		return types.NewString(reflected.constant.Name), nil
+	})
+	addConstantReflector(class, "getValue", 0, func(vm *VM, reflected *reflectedConstant, args []*types.Value) (*types.Value, error) {
+		return reflected.constant.Value, nil
+	})
+	addConstantReflector(class, "getModifiers", 0, func(vm *VM, reflected *reflectedConstant, args []*types.Value) (*types.Value, error) {
+		return types.NewInt(reflection.ConstantModifiers(reflected.constant)), nil
+	})
+	addConstantReflector(class, "getDeclaringClass", 0, func(vm *VM, reflected *reflectedConstant, args []*types.Value) (*types.Value, error) {
+		return vm.newReflectionClass(reflected.class), nil
+	})
+	addConstantReflector(class, "getDocComment", 0, func(vm *VM, reflected *reflectedConstant, args []*types.Value) (*types.Value, error) {
+		return docComment(reflected.constant.DocComment), nil
+	})
+	addConstantReflector(class, "getAttributes", 2, func(vm *VM, reflected *reflectedConstant, args []*types.Value) (*types.Value, error) {
+		return vm.reflectAttributes("ReflectionClassConstant::getAttributes", reflected.constant.Attributes,
+			attributeTargetClassConstant, reflected.class, args)
+	})
+	return class
+}
+
+// newReflectionConstant creates a ReflectionClassConstant object
+func (vm *VM) newReflectionConstant(class *types.ClassEntry, constant *types.ClassConstant) *types.Value {
+	obj := types.NewObjectFromClass(vm.classes["ReflectionClassConstant"])
+	obj.Internal = &reflectedConstant{class: class, constant: constant}
+	setReflectionNames(obj, "name", constant.Name, "class", class.Name)
+	return types.NewObject(obj)
+}
+
+// ============================================================================
+// ReflectionProperty
+// ============================================================================
+
+// addPropertyReflector adds a method of ReflectionProperty
+func addPropertyReflector(class *types.ClassEntry, name string, numParams int, fn func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error)) *types.MethodDef {
+	return addNativeMethod(class, name, numParams, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		reflected, ok := this.Internal.(*reflectedProperty)
+		if !ok {
+			return nil, vm.ThrowError("Error", "Internal error: Failed to retrieve the reflection object")
+		}
+		return fn(vm, reflected, derefArgs(args))
+	})
+}
+
+// reflectionPropertyClass builds ReflectionProperty. Properties are all
+// accessible through reflection, so setAccessible() does nothing.
+func reflectionPropertyClass(reflector *types.InterfaceEntry) *types.ClassEntry {
+	class := types.NewClassEntry("ReflectionProperty")
+	class.Interfaces = append(class.Interfaces, reflector)
+	addReflectionNameProperties(class, "name", "class")
+	addModifierConstants(class, map[string]int64{
+		"IS_PUBLIC":    reflection.IS_PUBLIC,
+		"IS_PROTECTED": reflection.IS_PROTECTED,
+		"IS_PRIVATE":   reflection.IS_PRIVATE,
+		"IS_STATIC":    reflection.IS_STATIC,
+		"IS_READONLY":  reflection.IS_READONLY,
+	})
+
+	addNativeMethod(class, "__construct", 2, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		args = derefArgs(args)
+		owner, err := vm.reflectionClassArg("ReflectionProperty::__construct", args, "Class")
+		if err != nil {
+			return nil, err
+		}
+		name := reflectionStringArg(args, 1)
+		reflected := &reflectedProperty{class: owner, name: name}
+		if prop, ok := reflection.FindProperty(owner, name); ok {
+			reflected.prop = prop
+			reflected.class = propertyDeclaringClass(vm, owner, prop)
+		} else if args[0].Type() != types.TypeObject || args[0].ToObject().Properties[name] == nil {
+			return nil, vm.ThrowError("ReflectionException", "Property %s::$%s does not exist", owner.Name, name)
+		}
+		this.Internal = reflected
+		setReflectionNames(this, "name", name, "class", reflected.class.Name)
+		return nil, nil
+	})
+	class.Constructor = class.Methods["__construct"]
+	class.Constructor.IsConstructor = true
+
+	visibilities := map[string]types.PropertyVisibility{
+		"isPublic":    types.VisibilityPublic,
+		"isProtected": types.VisibilityProtected,
+		"isPrivate":   types.VisibilityPrivate,
+	}
+	for name, visibility := range visibilities {
+		visibility := visibility
+		addPropertyReflector(class, name, 0, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+			if reflected.prop == nil {
+				return types.NewBool(visibility == types.VisibilityPublic), nil
+			}
+			return types.NewBool(reflected.prop.Visibility == visibility), nil
+		})
+	}
+	addPropertyReflector(class, "isStatic", 0, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.prop != nil && reflected.prop.IsStatic), nil
+	})
+	addPropertyReflector(class, "isReadOnly", 0, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.prop != nil && reflected.prop.IsReadOnly), nil
+	})
+	addPropertyReflector(class, "isDefault", 0, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.prop != nil), nil
+	})
+	addPropertyReflector(class, "isPromoted", 0, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		if reflected.class.Constructor != nil {
+			for _, param := range reflected.class.Constructor.Parameters {
+				if param.IsPromoted && param.Name == reflected.name {
+					return types.NewBool(true), nil
+				}
+			}
+		}
+		return types.NewBool(false), nil
+	})
+	addPropertyReflector(class, "getName", 0, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		return types.NewString(reflected.name), nil
+	})
+	addPropertyReflector(class, "getModifiers", 0, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		if reflected.prop == nil {
+			return types.NewInt(reflection.IS_PUBLIC), nil
+		}
+		return types.NewInt(reflection.PropertyModifiers(reflected.prop)), nil
+	})
+	addPropertyReflector(class, "getDeclaringClass", 0, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		return vm.newReflectionClass(reflected.class), nil
+	})
+	addPropertyReflector(class, "getDocComment", 0, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		if reflected.prop == nil {
+			return types.NewBool(false), nil
+		}
+		return docComment(reflected.prop.DocComment), nil
+	})
+	addPropertyReflector(class, "getAttributes", 2, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		var attrs []*types.Attribute
+		if reflected.prop != nil {
+			attrs = reflected.prop.Attributes
+		}
+		return vm.reflectAttributes("ReflectionProperty::getAttributes", attrs, attributeTargetProperty, reflected.class, args)
+	})
+	addPropertyReflector(class, "setAccessible", 1, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		return nil, nil
+	})
+	addPropertyReflector(class, "hasType", 0, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.prop != nil && reflected.prop.Type != ""), nil
+	})
+	addPropertyReflector(class, "getType", 0, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		if reflected.prop == nil {
+			return types.NewNull(), nil
+		}
+		return vm.newReflectionType(reflected.prop.Type), nil
+	})
+	addPropertyReflector(class, "hasDefaultValue", 0, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.prop != nil && reflected.prop.Default != nil), nil
+	})
+	addPropertyReflector(class, "getDefaultValue", 0, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		if reflected.prop == nil || reflected.prop.Default == nil {
+			return types.NewNull(), nil
+		}
+		return reflected.prop.Default.Copy(), nil
+	})
+
+	addPropertyReflector(class, "getValue", 1, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		if reflected.prop != nil && reflected.prop.IsStatic {
+			value, ok := reflected.class.GetStaticProperty(reflected.name)
+			if !ok {
+				return types.NewNull(), nil
+			}
+			return value.Deref(), nil
+		}
+		obj, err := vm.reflectedPropertyObject(reflected, "getValue", args)
+		if err != nil {
+			return nil, err
+		}
+		prop, ok := obj.Properties[obj.PropertyKey(reflected.name, reflected.class)]
+		if !ok || prop.Value == nil {
+			if reflected.prop != nil && reflected.prop.Type != "" {
+				return nil, vm.ThrowError("Error", "Typed property %s::$%s must not be accessed before initialization", reflected.class.Name, reflected.name)
+			}
+			return types.NewNull(), nil
+		}
+		return prop.Value.Deref(), nil
+	})
+	addPropertyReflector(class, "setValue", 2, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		if reflected.prop != nil && reflected.prop.IsStatic {
+			value := types.NewNull()
+			if len(args) > 0 {
+				value = args[len(args)-1]
+			}
+			reflected.class.SetStaticProperty(reflected.name, assignValue(value))
+			return nil, nil
+		}
+		obj, err := vm.reflectedPropertyObject(reflected, "setValue", args)
+		if err != nil {
+			return nil, err
+		}
+		value := types.NewNull()
+		if len(args) > 1 {
+			value = args[1]
+		}
+		if err := obj.AssignProperty(reflected.name, assignValue(value), reflected.class); err != nil {
+			return nil, vm.ThrowError("Error", "%s", err.Error())
+		}
+		return nil, nil
+	})
+	addPropertyReflector(class, "isInitialized", 1, func(vm *VM, reflected *reflectedProperty, args []*types.Value) (*types.Value, error) {
+		if reflected.prop != nil && reflected.prop.IsStatic {
+			_, ok := reflected.class.GetStaticProperty(reflected.name)
+			return types.NewBool(ok), nil
+		}
+		obj, err := vm.reflectedPropertyObject(reflected, "isInitialized", args)
+		if err != nil {
+			return nil, err
+		}
+		prop, ok := obj.Properties[obj.PropertyKey(reflected.name, reflected.class)]
+		return types.NewBool(ok && prop.Value != nil), nil
+	})
+	return class
+}
+
+// newReflectionProperty creates a ReflectionProperty object
+func (vm *VM) newReflectionProperty(class *types.ClassEntry, prop *types.PropertyDef, name string) *types.Value {
+	declaring := propertyDeclaringClass(vm, class, prop)
+	obj := types.NewObjectFromClass(vm.classes["ReflectionProperty"])
+	obj.Internal = &reflectedProperty{class: declaring, prop: prop, name: name}
+	setReflectionNames(obj, "name", name, "class", declaring.Name)
+	return types.NewObject(obj)
+}
+
+// propertyDeclaringClass returns the class declaring a property of class
+func propertyDeclaringClass(vm *VM, class *types.ClassEntry, prop *types.PropertyDef) *types.ClassEntry {
+	if prop != nil && prop.DeclaringClass != "" && prop.DeclaringClass != class.Name {
+		if declaring, ok := vm.lookupClass(prop.DeclaringClass); ok && !declaring.IsTrait {
+			return declaring
+		}
+	}
+	return class
+}
+
+// reflectedPropertyObject returns the object argument of a
+// ReflectionProperty method reading or writing an instance property
+func (vm *VM) reflectedPropertyObject(reflected *reflectedProperty, method string, args []*types.Value) (*types.Object, error) {
+	if len(args) == 0 || args[0].Type() != types.TypeObject {
+		return nil, vm.ThrowError("TypeError", "ReflectionProperty::%s(): Argument #1 ($object) must be provided for instance properties", method)
+	}
+	obj := args[0].ToObject()
+	if reflected.prop != nil && !vm.isInstanceOf(obj.ClassEntry, reflected.class.Name) {
+		return nil, vm.ThrowError("ReflectionException", "Given object is not an instance of the class this property was declared in")
+	}
+	return obj, nil
+}
@@ -0,0 +1,722 @@
+package vm
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/stdlib/reflection"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// ReflectionFunctionAbstract, ReflectionFunction and ReflectionMethod
+// ============================================================================
+
+// reflectedFunction is the state of a ReflectionFunction or
+// ReflectionMethod object
+type reflectedFunction struct {
+	name       string
+	params     []*types.ParameterDef
+	returnType string
+	byRef      bool
+	attributes []*types.Attribute
+	docComment string
+	internal   bool              // Implemented in Go
+	static     bool              // Static method or static closure
+	callable   *types.Value      // Function name or Closure (functions only)
+	class      *types.ClassEntry // Class declaring the method (methods only)
+	method     *types.MethodDef
+}
+
+// reflectedParameter is the state of a ReflectionParameter object
+type reflectedParameter struct {
+	param    *types.ParameterDef
+	position int
+	function *reflectedFunction
+}
+
+// functionParameters returns the parameter definitions of a function,
+// naming them $arg0, $arg1... when only their number is known
+func functionParameters(params []*types.ParameterDef, numParams int) []*types.ParameterDef {
+	if params != nil || numParams == 0 {
+		return params
+	}
+	params = make([]*types.ParameterDef, numParams)
+	for i := range params {
+		params[i] = &types.ParameterDef{Name: fmt.Sprintf("arg%d", i)}
+	}
+	return params
+}
+
+// isClosure reports whether a reflected function is a Closure
+func (f *reflectedFunction) isClosure() bool {
+	return f.callable != nil && f.callable.Type() == types.TypeObject
+}
+
+// closure returns the reflected Closure, nil for functions and methods
+func (f *reflectedFunction) closure() *Closure {
+	if !f.isClosure() {
+		return nil
+	}
+	closure, _ := f.callable.ToObject().Internal.(*Closure)
+	return closure
+}
+
+// reflectFunction describes a function or Closure
+func (vm *VM) reflectFunction(callable *types.Value) (*reflectedFunction, error) {
+	callable = callable.Deref()
+	if callable.Type() == types.TypeObject {
+		closure, ok := callable.ToObject().Internal.(*Closure)
+		if !ok {
+			return nil, vm.ThrowError("TypeError", "ReflectionFunction::__construct(): Argument #1 ($function) must be of type Closure|string, %s given", callable.ToObject().ClassName)
+		}
+		reflected := &reflectedFunction{name: "{closure}", callable: callable, static: closure.Static, internal: closure.Function == nil}
+		if fn := closure.Function; fn != nil {
+			reflected.params = functionParameters(fn.Parameters, fn.NumParams)
+			reflected.returnType = fn.ReturnType
+			reflected.byRef = closure.ReturnByRef || fn.ReturnByRef
+			reflected.attributes = fn.Attributes
+			reflected.docComment = fn.DocComment
+		}
+		return reflected, nil
+	}
+
+	name := strings.TrimPrefix(callable.ToString(), "\\")
+	target, ok := vm.lookupFunction(name)
+	if !ok {
+		return nil, vm.ThrowError("ReflectionException", "Function %s() does not exist", name)
+	}
+	reflected := &reflectedFunction{name: target.Name, callable: types.NewString(target.Name), internal: target.Function == nil}
+	if signature, ok := vm.signatures[strings.ToLower(name)]; ok && target.Function == nil {
+		reflected.params = signature.params
+		reflected.returnType = signature.returnType
+	}
+	if fn := target.Function; fn != nil {
+		reflected.name = fn.Name
+		reflected.params = functionParameters(fn.Parameters, fn.NumParams)
+		reflected.returnType = fn.ReturnType
+		reflected.byRef = fn.ReturnByRef
+		reflected.attributes = fn.Attributes
+		reflected.docComment = fn.DocComment
+	}
+	return reflected, nil
+}
+
+// reflectMethod describes a method of a class
+func (vm *VM) reflectMethod(class *types.ClassEntry, method *types.MethodDef) *reflectedFunction {
+	return &reflectedFunction{
+		name:       method.Name,
+		params:     functionParameters(method.Parameters, method.NumParams),
+		returnType: method.ReturnType,
+		byRef:      method.ReturnByRef,
+		attributes: method.Attributes,
+		docComment: method.DocComment,
+		internal:   method.Handler != nil,
+		static:     method.IsStatic,
+		class:      vm.methodScope(class, method),
+		method:     method,
+	}
+}
+
+// addFunctionReflector adds a method of ReflectionFunctionAbstract or its
+// subclasses
+func addFunctionReflector(class *types.ClassEntry, name string, numParams int, fn func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error)) *types.MethodDef {
+	return addNativeMethod(class, name, numParams, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		reflected, ok := this.Internal.(*reflectedFunction)
+		if !ok {
+			return nil, vm.ThrowError("Error", "Internal error: Failed to retrieve the reflection object")
+		}
+		return fn(vm, reflected, derefArgs(args))
+	})
+}
+
+// registerReflectionFunctionClasses registers ReflectionFunctionAbstract,
+// ReflectionFunction, ReflectionMethod, ReflectionParameter and the
+// ReflectionType classes
+func (vm *VM) registerReflectionFunctionClasses(reflector *types.InterfaceEntry) {
+	abstract := reflectionFunctionAbstractClass(reflector)
+	vm.classes[abstract.Name] = abstract
+	vm.classes["ReflectionFunction"] = reflectionFunctionClass(abstract)
+	vm.classes["ReflectionMethod"] = reflectionMethodClass(abstract)
+	vm.classes["ReflectionParameter"] = reflectionParameterClass(reflector)
+
+	reflectionType := reflectionTypeClass()
+	vm.classes[reflectionType.Name] = reflectionType
+	vm.classes["ReflectionNamedType"] = reflectionNamedTypeClass(reflectionType)
+	vm.classes["ReflectionUnionType"] = reflectionCompositeTypeClass("ReflectionUnionType", reflectionType)
+	vm.classes["ReflectionIntersectionType"] = reflectionCompositeTypeClass("ReflectionIntersectionType", reflectionType)
+}
+
+// reflectionFunctionAbstractClass builds ReflectionFunctionAbstract, the
+// methods shared by ReflectionFunction and ReflectionMethod
+func reflectionFunctionAbstractClass(reflector *types.InterfaceEntry) *types.ClassEntry {
+	class := types.NewClassEntry("ReflectionFunctionAbstract")
+	class.IsAbstract = true
+	class.Interfaces = append(class.Interfaces, reflector)
+	addReflectionNameProperties(class, "name")
+
+	addFunctionReflector(class, "getName", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewString(reflected.name), nil
+	})
+	addFunctionReflector(class, "getShortName", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewString(lastNameSegment(reflected.name)), nil
+	})
+	addFunctionReflector(class, "getNamespaceName", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewString(namespaceOf(reflected.name)), nil
+	})
+	addFunctionReflector(class, "inNamespace", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(namespaceOf(reflected.name) != ""), nil
+	})
+	addFunctionReflector(class, "getNumberOfParameters", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewInt(int64(len(reflected.params))), nil
+	})
+	addFunctionReflector(class, "getNumberOfRequiredParameters", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewInt(int64(reflection.RequiredParameters(reflected.params))), nil
+	})
+	addFunctionReflector(class, "getParameters", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		params := types.NewEmptyArray()
+		for i, param := range reflected.params {
+			params.Append(vm.newReflectionParameter(&reflectedParameter{param: param, position: i, function: reflected}))
+		}
+		return types.NewArray(params), nil
+	})
+	addFunctionReflector(class, "hasReturnType", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.returnType != ""), nil
+	})
+	addFunctionReflector(class, "getReturnType", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return vm.newReflectionType(reflected.returnType), nil
+	})
+	addFunctionReflector(class, "returnsReference", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.byRef), nil
+	})
+	addFunctionReflector(class, "isVariadic", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		n := len(reflected.params)
+		return types.NewBool(n > 0 && reflected.params[n-1].IsVariadic), nil
+	})
+	addFunctionReflector(class, "isInternal", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.internal), nil
+	})
+	addFunctionReflector(class, "isUserDefined", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(!reflected.internal), nil
+	})
+	addFunctionReflector(class, "isClosure", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.isClosure()), nil
+	})
+	addFunctionReflector(class, "isStatic", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.static), nil
+	})
+	addFunctionReflector(class, "getDocComment", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return docComment(reflected.docComment), nil
+	})
+	addFunctionReflector(class, "getClosureThis", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		if closure := reflected.closure(); closure != nil && closure.This != nil {
+			return types.NewObject(closure.This), nil
+		}
+		return types.NewNull(), nil
+	})
+	addFunctionReflector(class, "getClosureScopeClass", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		if closure := reflected.closure(); closure != nil && closure.Scope != nil {
+			return vm.newReflectionClass(closure.Scope), nil
+		}
+		return types.NewNull(), nil
+	})
+	addFunctionReflector(class, "getClosureCalledClass", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		if closure := reflected.closure(); closure != nil && closure.CalledClass != nil {
+			return vm.newReflectionClass(closure.CalledClass), nil
+		}
+		return types.NewNull(), nil
+	})
+	addFunctionReflector(class, "getClosureUsedVariables", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		used := types.NewEmptyArray()
+		if closure := reflected.closure(); closure != nil {
+			for _, name := range slices.Sorted(maps.Keys(closure.CapturedVars)) {
+				used.Set(types.NewString(name), closure.CapturedVars[name])
+			}
+		}
+		return types.NewArray(used), nil
+	})
+	addFunctionReflector(class, "getAttributes", 2, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		if reflected.method != nil {
+			return vm.reflectAttributes("ReflectionMethod::getAttributes", reflected.attributes, attributeTargetMethod, reflected.class, args)
+		}
+		return vm.reflectAttributes("ReflectionFunction::getAttributes", reflected.attributes, attributeTargetFunction, nil, args)
+	})
+	return class
+}
+
+// reflectionFunctionClass builds ReflectionFunction
+func reflectionFunctionClass(abstract *types.ClassEntry) *types.ClassEntry {
+	class := types.NewClassEntry("ReflectionFunction")
+	class.InheritFrom(abstract)
+
+	addNativeMethod(class, "__construct", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		if len(args) == 0 {
+			return nil, vm.ThrowError("ArgumentCountError", "ReflectionFunction::__construct() expects exactly 1 argument, 0 given")
+		}
+		reflected, err := vm.reflectFunction(args[0])
+		if err != nil {
+			return nil, err
+		}
+		this.Internal = reflected
+		setReflectionNames(this, "name", reflected.name)
+		return nil, nil
+	})
+	class.Constructor = class.Methods["__construct"]
+	class.Constructor.IsConstructor = true
+
+	addFunctionReflector(class, "invoke", -1, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return vm.invokeReflectedFunction(reflected, &CallParams{params: args})
+	})
+	addFunctionReflector(class, "invokeArgs", 1, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		params := &CallParams{}
+		if len(args) > 0 {
+			if err := vm.unpackArguments(params, args[0]); err != nil {
+				return nil, err
+			}
+		}
+		return vm.invokeReflectedFunction(reflected, params)
+	})
+	addFunctionReflector(class, "getClosure", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		if reflected.isClosure() {
+			return reflected.callable, nil
+		}
+		target, err := vm.resolveCallable(reflected.callable)
+		if err != nil {
+			return nil, err
+		}
+		return newClosureObject(closureFromTarget(target)), nil
+	})
+	return class
+}
+
+// invokeReflectedFunction calls the function a ReflectionFunction reflects
+func (vm *VM) invokeReflectedFunction(reflected *reflectedFunction, params *CallParams) (*types.Value, error) {
+	target, err := vm.resolveCallable(reflected.callable)
+	if err != nil {
+		return nil, vm.ThrowError("Error", "%s", err.Error())
+	}
+	args, rest, err := vm.bindArguments(target.Name, target.parameters(), params)
+	if err != nil {
+		return nil, err
+	}
+	target.NamedRest = rest
+	return vm.invokeTarget(target, args)
+}
+
+// reflectionMethodClass builds ReflectionMethod. Methods are all callable
+// through reflection, so setAccessible() does nothing.
+func reflectionMethodClass(abstract *types.ClassEntry) *types.ClassEntry {
+	class := types.NewClassEntry("ReflectionMethod")
+	class.InheritFrom(abstract)
+	addReflectionNameProperties(class, "class")
+	addModifierConstants(class, map[string]int64{
+		"IS_PUBLIC":    reflection.IS_PUBLIC,
+		"IS_PROTECTED": reflection.IS_PROTECTED,
+		"IS_PRIVATE":   reflection.IS_PRIVATE,
+		"IS_STATIC":    reflection.IS_STATIC,
+		"IS_FINAL":     reflection.IS_FINAL,
+		"IS_ABSTRACT":  reflection.IS_ABSTRACT,
+	})
+
+	addNativeMethod(class, "__construct", 2, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		args = derefArgs(args)
+		if len(args) == 0 {
+			return nil, vm.ThrowError("ArgumentCountError", "ReflectionMethod::__construct() expects at least 1 argument, 0 given")
+		}
+		if len(args) == 1 || args[1].IsNull() {
+			name := args[0].ToString()
+			idx := strings.Index(name, "::")
+			if args[0].Type() == types.TypeObject || idx < 0 {
+				return nil, vm.ThrowError("ReflectionException", "ReflectionMethod::__construct(): Argument #1 ($objectOrMethod) must be a valid method name")
+			}
+			args = []*types.Value{types.NewString(name[:idx]), types.NewString(name[idx+2:])}
+		}
+		owner, err := vm.reflectionClassArg("ReflectionMethod::__construct", args, "Class")
+		if err != nil {
+			return nil, err
+		}
+		name := args[1].ToString()
+		method, ok := reflection.FindMethod(owner, name)
+		if !ok {
+			return nil, vm.ThrowError("ReflectionException", "Method %s::%s() does not exist", owner.Name, name)
+		}
+		reflected := vm.reflectMethod(owner, method)
+		this.Internal = reflected
+		setReflectionNames(this, "name", method.Name, "class", reflected.class.Name)
+		return nil, nil
+	})
+	class.Constructor = class.Methods["__construct"]
+	class.Constructor.IsConstructor = true
+
+	visibilities := map[string]types.PropertyVisibility{
+		"isPublic":    types.VisibilityPublic,
+		"isProtected": types.VisibilityProtected,
+		"isPrivate":   types.VisibilityPrivate,
+	}
+	for name, visibility := range visibilities {
+		visibility := visibility
+		addFunctionReflector(class, name, 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+			return types.NewBool(reflected.method.Visibility == visibility), nil
+		})
+	}
+	addFunctionReflector(class, "isAbstract", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.method.IsAbstract), nil
+	})
+	addFunctionReflector(class, "isFinal", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.method.IsFinal), nil
+	})
+	addFunctionReflector(class, "isConstructor", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.method.IsConstructor || strings.EqualFold(reflected.name, "__construct")), nil
+	})
+	addFunctionReflector(class, "isDestructor", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.method.IsDestructor || strings.EqualFold(reflected.name, "__destruct")), nil
+	})
+	addFunctionReflector(class, "getModifiers", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return types.NewInt(reflection.MethodModifiers(reflected.method)), nil
+	})
+	addFunctionReflector(class, "getDeclaringClass", 0, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return vm.newReflectionClass(reflected.class), nil
+	})
+	addFunctionReflector(class, "setAccessible", 1, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		return nil, nil
+	})
+
+	addFunctionReflector(class, "invoke", -1, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		var object *types.Value
+		if len(args) > 0 {
+			object, args = args[0], args[1:]
+		}
+		return vm.invokeReflectedMethod(reflected, object, &CallParams{params: args})
+	})
+	addFunctionReflector(class, "invokeArgs", 2, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		var object *types.Value
+		if len(args) > 0 {
+			object = args[0]
+		}
+		params := &CallParams{}
+		if len(args) > 1 {
+			if err := vm.unpackArguments(params, args[1]); err != nil {
+				return nil, err
+			}
+		}
+		return vm.invokeReflectedMethod(reflected, object, params)
+	})
+	addFunctionReflector(class, "getClosure", 1, func(vm *VM, reflected *reflectedFunction, args []*types.Value) (*types.Value, error) {
+		var object *types.Value
+		if len(args) > 0 {
+			object = args[0]
+		}
+		target, err := vm.reflectedMethodTarget(reflected, object)
+		if err != nil {
+			return nil, err
+		}
+		return newClosureObject(closureFromTarget(target)), nil
+	})
+	return class
+}
+
+// reflectedMethodTarget builds the call target of a reflected method on
+// an object, which static methods ignore
+func (vm *VM) reflectedMethodTarget(reflected *reflectedFunction, object *types.Value) (*callTarget, error) {
+	method := reflected.method
+	if method.IsAbstract {
+		return nil, vm.ThrowError("ReflectionException", "Trying to invoke abstract method %s::%s()", reflected.class.Name, method.Name)
+	}
+	if method.IsStatic {
+		return vm.methodTarget(reflected.class, nil, method), nil
+	}
+	if object == nil || object.Type() != types.TypeObject {
+		return nil, vm.ThrowError("ReflectionException", "Trying to invoke non static method %s::%s() without an object", reflected.class.Name, method.Name)
+	}
+	obj := object.ToObject()
+	if !vm.isInstanceOf(obj.ClassEntry, reflected.class.Name) {
+		return nil, vm.ThrowError("ReflectionException", "Given object is not an instance of the class this method was declared in")
+	}
+	return vm.methodTarget(obj.ClassEntry, obj, method), nil
+}
+
+// invokeReflectedMethod calls the method a ReflectionMethod reflects,
+// whatever its visibility
+func (vm *VM) invokeReflectedMethod(reflected *reflectedFunction, object *types.Value, params *CallParams) (*types.Value, error) {
+	target, err := vm.reflectedMethodTarget(reflected, object)
+	if err != nil {
+		return nil, err
+	}
+	args, rest, err := vm.bindArguments(target.Name, target.parameters(), params)
+	if err != nil {
+		return nil, err
+	}
+	target.NamedRest = rest
+	return vm.invokeTarget(target, args)
+}
+
+// newReflectionMethod creates a ReflectionMethod object
+func (vm *VM) newReflectionMethod(class *types.ClassEntry, method *types.MethodDef) *types.Value {
+	return vm.newReflectionFunctionObject(vm.reflectMethod(class, method))
+}
+
+// newReflectionFunctionObject creates the ReflectionFunction or
+// ReflectionMethod object of a reflected function
+func (vm *VM) newReflectionFunctionObject(reflected *reflectedFunction) *types.Value {
+	if reflected.method != nil {
+		obj := types.NewObjectFromClass(vm.classes["ReflectionMethod"])
+		obj.Internal = reflected
+		setReflectionNames(obj, "name", reflected.name, "class", reflected.class.Name)
+		return types.NewObject(obj)
+	}
+	obj := types.NewObjectFromClass(vm.classes["ReflectionFunction"])
+	obj.Internal = reflected
+	setReflectionNames(obj, "name", reflected.name)
+	return types.NewObject(obj)
+}
+
+// ============================================================================
+// ReflectionParameter
+// ============================================================================
+
+// addParameterReflector adds a method of ReflectionParameter
+func addParameterReflector(class *types.ClassEntry, name string, numParams int, fn func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error)) *types.MethodDef {
+	return addNativeMethod(class, name, numParams, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		reflected, ok := this.Internal.(*reflectedParameter)
+		if !ok {
+			return nil, vm.ThrowError("Error", "Internal error: Failed to retrieve the reflection object")
+		}
+		return fn(vm, reflected, derefArgs(args))
+	})
+}
+
+// reflectionParameterClass builds ReflectionParameter
+func reflectionParameterClass(reflector *types.InterfaceEntry) *types.ClassEntry {
+	class := types.NewClassEntry("ReflectionParameter")
+	class.Interfaces = append(class.Interfaces, reflector)
+	addReflectionNameProperties(class, "name")
+
+	addNativeMethod(class, "__construct", 2, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		args = derefArgs(args)
+		if len(args) < 2 {
+			return nil, vm.ThrowError("ArgumentCountError", "ReflectionParameter::__construct() expects exactly 2 arguments, %d given", len(args))
+		}
+		function, err := vm.reflectParameterFunction(args[0])
+		if err != nil {
+			return nil, err
+		}
+		for i, param := range function.params {
+			if (args[1].IsInt() && int64(i) == args[1].ToInt()) || (!args[1].IsInt() && param.Name == args[1].ToString()) {
+				this.Internal = &reflectedParameter{param: param, position: i, function: function}
+				setReflectionNames(this, "name", param.Name)
+				return nil, nil
+			}
+		}
+		if args[1].IsInt() {
+			return nil, vm.ThrowError("ReflectionException", "The parameter specified by its offset could not be found")
+		}
+		return nil, vm.ThrowError("ReflectionException", "The parameter specified by its name could not be found")
+	})
+	class.Constructor = class.Methods["__construct"]
+	class.Constructor.IsConstructor = true
+
+	addParameterReflector(class, "getName", 0, func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error) {
+		return types.NewString(reflected.param.Name), nil
+	})
+	addParameterReflector(class, "getPosition", 0, func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error) {
+		return types.NewInt(int64(reflected.position)), nil
+	})
+	addParameterReflector(class, "isOptional", 0, func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.position >= reflection.RequiredParameters(reflected.function.params)), nil
+	})
+	addParameterReflector(class, "isDefaultValueAvailable", 0, func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.param.HasDefault), nil
+	})
+	addParameterReflector(class, "getDefaultValue", 0, func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error) {
+		if !reflected.param.HasDefault {
+			return nil, vm.ThrowError("ReflectionException", "Internal error: Failed to retrieve the default value")
+		}
+		if reflected.param.Default == nil {
+			return types.NewNull(), nil
+		}
+		return reflected.param.Default.Copy(), nil
+	})
+	addParameterReflector(class, "hasType", 0, func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.param.Type != ""), nil
+	})
+	addParameterReflector(class, "getType", 0, func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error) {
+		return vm.newReflectionType(reflected.param.Type), nil
+	})
+	addParameterReflector(class, "allowsNull", 0, func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error) {
+		t := types.ParseType(reflected.param.Type)
+		return types.NewBool(len(t.Alternatives) == 0 || t.IsNullable), nil
+	})
+	addParameterReflector(class, "isVariadic", 0, func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.param.IsVariadic), nil
+	})
+	addParameterReflector(class, "isPassedByReference", 0, func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.param.PassedByRef), nil
+	})
+	addParameterReflector(class, "canBePassedByValue", 0, func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(!reflected.param.PassedByRef), nil
+	})
+	addParameterReflector(class, "isPromoted", 0, func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(reflected.param.IsPromoted), nil
+	})
+	addParameterReflector(class, "getDeclaringFunction", 0, func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error) {
+		return vm.newReflectionFunctionObject(reflected.function), nil
+	})
+	addParameterReflector(class, "getDeclaringClass", 0, func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error) {
+		if reflected.function.class == nil {
+			return types.NewNull(), nil
+		}
+		return vm.newReflectionClass(reflected.function.class), nil
+	})
+	addParameterReflector(class, "getAttributes", 2, func(vm *VM, reflected *reflectedParameter, args []*types.Value) (*types.Value, error) {
+		return vm.reflectAttributes("ReflectionParameter::getAttributes", reflected.param.Attributes,
+			attributeTargetParameter, reflected.function.class, args)
+	})
+	return class
+}
+
+// reflectParameterFunction describes the function named by the first
+// argument of ReflectionParameter::__construct(): a function name, a
+// Closure or a [class, method] pair
+func (vm *VM) reflectParameterFunction(function *types.Value) (*reflectedFunction, error) {
+	if !function.IsArray() {
+		return vm.reflectFunction(function)
+	}
+	classOrObj, _ := function.ToArray().Get(types.NewInt(0))
+	name, _ := function.ToArray().Get(types.NewInt(1))
+	if classOrObj == nil || name == nil {
+		return nil, vm.ThrowError("ReflectionException", "Expected array($object, $method) or array($classname, $method)")
+	}
+	class, err := vm.reflectionClassArg("ReflectionParameter::__construct", []*types.Value{classOrObj.Deref()}, "Class")
+	if err != nil {
+		return nil, err
+	}
+	method, ok := reflection.FindMethod(class, name.Deref().ToString())
+	if !ok {
+		return nil, vm.ThrowError("ReflectionException", "Method %s::%s() does not exist", class.Name, name.Deref().ToString())
+	}
+	return vm.reflectMethod(class, method), nil
+}
+
+// newReflectionParameter creates a ReflectionParameter object
+func (vm *VM) newReflectionParameter(reflected *reflectedParameter) *types.Value {
+	obj := types.NewObjectFromClass(vm.classes["ReflectionParameter"])
+	obj.Internal = reflected
+	setReflectionNames(obj, "name", reflected.param.Name)
+	return types.NewObject(obj)
+}
+
+// ============================================================================
+// ReflectionType, ReflectionNamedType, ReflectionUnionType and
+// ReflectionIntersectionType
+// ============================================================================
+
+// addTypeReflector adds a method of ReflectionNamedType
+func addTypeReflector(class *types.ClassEntry, name string, fn func(t reflection.NamedType) *types.Value) *types.MethodDef {
+	return addNativeMethod(class, name, 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		t, ok := this.Internal.(reflection.NamedType)
+		if !ok {
+			return nil, vm.ThrowError("Error", "Internal error: Failed to retrieve the reflection object")
+		}
+		return fn(t), nil
+	})
+}
+
+// addCompositeTypeReflector adds a method of ReflectionUnionType or
+// ReflectionIntersectionType, which describe their type in DNF
+func addCompositeTypeReflector(class *types.ClassEntry, name string, fn func(vm *VM, t *types.TypeInfo) *types.Value) *types.MethodDef {
+	return addNativeMethod(class, name, 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		t, ok := this.Internal.(*types.TypeInfo)
+		if !ok {
+			return nil, vm.ThrowError("Error", "Internal error: Failed to retrieve the reflection object")
+		}
+		return fn(vm, t), nil
+	})
+}
+
+// reflectionTypeClass builds ReflectionType
+func reflectionTypeClass() *types.ClassEntry {
+	class := types.NewClassEntry("ReflectionType")
+	class.IsAbstract = true
+	addNativeMethod(class, "allowsNull", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		switch t := this.Internal.(type) {
+		case reflection.NamedType:
+			return types.NewBool(t.AllowsNull), nil
+		case *types.TypeInfo:
+			return types.NewBool(t.IsNullable), nil
+		}
+		return nil, vm.ThrowError("Error", "Internal error: Failed to retrieve the reflection object")
+	})
+	addNativeMethod(class, "__toString", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		if t, ok := this.Internal.(fmt.Stringer); ok {
+			return types.NewString(t.String()), nil
+		}
+		return nil, vm.ThrowError("Error", "Internal error: Failed to retrieve the reflection object")
+	})
+	return class
+}
+
+// reflectionNamedTypeClass builds ReflectionNamedType
+func reflectionNamedTypeClass(parent *types.ClassEntry) *types.ClassEntry {
+	class := types.NewClassEntry("ReflectionNamedType")
+	class.InheritFrom(parent)
+	addTypeReflector(class, "getName", func(t reflection.NamedType) *types.Value {
+		return types.NewString(t.Name)
+	})
+	addTypeReflector(class, "isBuiltin", func(t reflection.NamedType) *types.Value {
+		return types.NewBool(t.Builtin)
+	})
+	return class
+}
+
+// reflectionCompositeTypeClass builds ReflectionUnionType or
+// ReflectionIntersectionType, whose getTypes() lists their members
+func reflectionCompositeTypeClass(name string, parent *types.ClassEntry) *types.ClassEntry {
+	class := types.NewClassEntry(name)
+	class.InheritFrom(parent)
+	addCompositeTypeReflector(class, "getTypes", func(vm *VM, t *types.TypeInfo) *types.Value {
+		var members []*types.Value
+		if len(t.Alternatives) == 1 {
+			for _, name := range t.Alternatives[0] {
+				members = append(members, vm.newReflectionType(name))
+			}
+		} else {
+			for _, alternative := range t.Alternatives {
+				members = append(members, vm.reflectionTypeObject(&types.TypeInfo{Alternatives: [][]string{alternative}}))
+			}
+		}
+		return types.NewArray(types.NewArrayFromSlice(members))
+	})
+	return class
+}
+
+// newReflectionType creates the ReflectionType of a type declaration,
+// null when there is none
+func (vm *VM) newReflectionType(decl string) *types.Value {
+	info := types.ParseType(decl)
+	if len(info.Alternatives) == 0 {
+		return types.NewNull()
+	}
+	return vm.reflectionTypeObject(info)
+}
+
+// reflectionTypeObject creates the ReflectionType describing a parsed
+// type: a ReflectionNamedType for a single type, also when nullable (?T
+// and T|null), a ReflectionIntersectionType for A&B and a
+// ReflectionUnionType for other unions, including DNF types
+func (vm *VM) reflectionTypeObject(info *types.TypeInfo) *types.Value {
+	class := "ReflectionUnionType"
+	var internal interface{} = info
+	switch named := info.String(); {
+	case !strings.ContainsAny(named, "|&"):
+		t, _ := reflection.ParseType(named)
+		class, internal = "ReflectionNamedType", t
+	case len(info.Alternatives) == 1:
+		class = "ReflectionIntersectionType"
+	}
+
+	obj := types.NewObjectFromClass(vm.classes[class])
+	obj.Internal = internal
+	return types.NewObject(obj)
+}
@@ -0,0 +1,504 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/stdlib/reflection"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// reflectClass creates a ReflectionClass of a class
+func reflectClass(t *testing.T, vm *VM, name string) *types.Value {
+	t.Helper()
+	obj, err := vm.instantiate(vm.classes["ReflectionClass"], &CallParams{params: []*types.Value{types.NewString(name)}})
+	if err != nil {
+		t.Fatalf("new ReflectionClass(%q) failed: %v", name, err)
+	}
+	return types.NewObject(obj)
+}
+
+// callReflectionMethod calls a method of a reflection object
+func callReflectionMethod(t *testing.T, vm *VM, obj *types.Value, method string, args ...*types.Value) *types.Value {
+	t.Helper()
+	result, err := vm.CallCallable(callableArray(obj, method), args)
+	if err != nil {
+		t.Fatalf("%s() failed: %v", method, err)
+	}
+	return result
+}
+
+// attributeWith builds an attribute with a positional and a named argument
+func attributeWith(name string, positional *types.Value, named string, value *types.Value) *types.Attribute {
+	attr := attributeOf(name, positional)
+	attr.Arguments = append(attr.Arguments, types.AttributeArgument{Name: named, Value: value})
+	return attr
+}
+
+func TestReflectionClass_GetAttributes(t *testing.T) {
+	vm := New()
+
+	// #[Attribute(Attribute::TARGET_CLASS | Attribute::TARGET_METHOD)]
+	// class Route { function __construct($path, $name = null) {...} }
+	route := types.NewClassEntry("Route")
+	route.Attributes = []*types.Attribute{{Name: "Attribute", Arguments: []types.AttributeArgument{{Expr: &types.ConstantExpr{
+		Operator: "|",
+		Left:     &types.ConstantExpr{Class: "Attribute", Constant: "TARGET_CLASS"},
+		Right:    &types.ConstantExpr{Class: "self", Constant: "TARGET"},
+	}}}}}
+	route.Constants["TARGET"] = &types.ClassConstant{Name: "TARGET", Value: types.NewInt(attributeTargetMethod)}
+	addNativeMethod(route, "__construct", 2, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		this.Properties["path"] = &types.Property{Value: args[0], Visibility: types.VisibilityPublic}
+		this.Properties["name"] = &types.Property{Value: args[1], Visibility: types.VisibilityPublic}
+		return nil, nil
+	})
+	route.Constructor = route.Methods["__construct"]
+	route.Constructor.Parameters = []*types.ParameterDef{{Name: "path"}, {Name: "name", HasDefault: true}}
+	vm.classes["Route"] = route
+	vm.classes["Plain"] = types.NewClassEntry("Plain")
+
+	// #[Route("/home", name: "home")] #[Plain] #[Missing] class Home {}
+	home := types.NewClassEntry("Home")
+	home.Attributes = []*types.Attribute{
+		attributeWith("Route", types.NewString("/home"), "name", types.NewString("home")),
+		{Name: "Plain"},
+		{Name: "Missing"},
+	}
+	vm.classes["Home"] = home
+
+	reflection := reflectClass(t, vm, "home")
+	if got := callReflectionMethod(t, vm, reflection, "getName").ToString(); got != "Home" {
+		t.Errorf("getName() = %q", got)
+	}
+
+	attrs := callReflectionMethod(t, vm, reflection, "getAttributes").ToArray()
+	if attrs.Len() != 3 {
+		t.Fatalf("Expected 3 attributes, got %d", attrs.Len())
+	}
+	first, _ := attrs.Get(types.NewInt(0))
+	if got := callReflectionMethod(t, vm, first, "getName").ToString(); got != "Route" {
+		t.Errorf("getName() = %q", got)
+	}
+	if got := callReflectionMethod(t, vm, first, "getTarget").ToInt(); got != attributeTargetClass {
+		t.Errorf("getTarget() = %d", got)
+	}
+	args := callReflectionMethod(t, vm, first, "getArguments").ToArray()
+	path, _ := args.Get(types.NewInt(0))
+	name, _ := args.Get(types.NewString("name"))
+	if args.Len() != 2 || path.ToString() != "/home" || name.ToString() != "home" {
+		t.Errorf("getArguments() = %v", args)
+	}
+
+	instance := callReflectionMethod(t, vm, first, "newInstance").ToObject()
+	if instance.ClassEntry != route || instance.Properties["path"].Value.ToString() != "/home" ||
+		instance.Properties["name"].Value.ToString() != "home" {
+		t.Errorf("Expected a Route built from the arguments, got %v", instance.Properties)
+	}
+
+	// Filtering by name
+	if got := callReflectionMethod(t, vm, reflection, "getAttributes", types.NewString("plain")).ToArray().Len(); got != 1 {
+		t.Errorf("getAttributes('plain') returned %d attributes", got)
+	}
+	instanceOf := types.NewInt(reflectionAttributeIsInstanceof)
+	if got := callReflectionMethod(t, vm, reflection, "getAttributes", types.NewString("Route"), instanceOf).ToArray().Len(); got != 1 {
+		t.Errorf("getAttributes(Route::class, IS_INSTANCEOF) returned %d attributes", got)
+	}
+
+	errors := []struct {
+		index   int64
+		message string
+	}{
+		{1, `Attempting to use non-attribute class "Plain" as attribute`},
+		{2, `Attribute class "Missing" not found`},
+	}
+	for _, tt := range errors {
+		attr, _ := attrs.Get(types.NewInt(tt.index))
+		_, err := vm.CallCallable(callableArray(attr, "newInstance"), nil)
+		thrown, ok := err.(*ThrowableError)
+		if !ok || throwableProperty(thrown.Object, "message").ToString() != tt.message {
+			t.Errorf("Expected error %q, got %v", tt.message, err)
+		}
+	}
+}
+
+func TestReflectionAttribute_TargetsAndRepetition(t *testing.T) {
+	vm := New()
+
+	// #[Attribute(Attribute::TARGET_METHOD)] class Get {}
+	get := types.NewClassEntry("Get")
+	get.Attributes = []*types.Attribute{attributeOf("Attribute", types.NewInt(attributeTargetMethod))}
+	vm.classes["Get"] = get
+
+	// #[Get] #[AllowDynamicProperties] #[AllowDynamicProperties] class Api {}
+	api := types.NewClassEntry("Api")
+	api.Attributes = []*types.Attribute{{Name: "Get"}, {Name: "AllowDynamicProperties"}, {Name: "AllowDynamicProperties"}}
+	vm.classes["Api"] = api
+
+	attrs := callReflectionMethod(t, vm, reflectClass(t, vm, "Api"), "getAttributes").ToArray()
+	tests := []struct {
+		index   int64
+		message string
+	}{
+		{0, `Attribute "Get" cannot target class (allowed targets: method)`},
+		{1, `Attribute "AllowDynamicProperties" must not be repeated`},
+	}
+	for _, tt := range tests {
+		attr, _ := attrs.Get(types.NewInt(tt.index))
+		_, err := vm.CallCallable(callableArray(attr, "newInstance"), nil)
+		thrown, ok := err.(*ThrowableError)
+		if !ok || throwableProperty(thrown.Object, "message").ToString() != tt.message {
+			t.Errorf("Expected error %q, got %v", tt.message, err)
+		}
+	}
+	if attr, _ := attrs.Get(types.NewInt(2)); !callReflectionMethod(t, vm, attr, "isRepeated").ToBool() {
+		t.Error("Expected a repeated attribute")
+	}
+
+	_, err := vm.CallCallable(callableArray(reflectClass(t, vm, "Api"), "getAttributes"), []*types.Value{types.NewNull(), types.NewInt(4)})
+	if thrown, ok := err.(*ThrowableError); !ok || thrown.Object.ClassEntry.Name != "ValueError" {
+		t.Errorf("Expected a ValueError for invalid flags, got %v", err)
+	}
+
+	_, err = vm.instantiate(vm.classes["ReflectionClass"], &CallParams{params: []*types.Value{types.NewString("Nope")}})
+	if thrown, ok := err.(*ThrowableError); !ok || throwableProperty(thrown.Object, "message").ToString() != `Class "Nope" does not exist` {
+		t.Errorf("Expected a ReflectionException, got %v", err)
+	}
+}
+
+// reflectionOf creates a reflection object through its constructor
+func reflectionOf(t *testing.T, vm *VM, class string, args ...*types.Value) *types.Value {
+	t.Helper()
+	obj, err := vm.instantiate(vm.classes[class], &CallParams{params: args})
+	if err != nil {
+		t.Fatalf("new %s() failed: %v", class, err)
+	}
+	return types.NewObject(obj)
+}
+
+// expectReflectionError checks that a call throws the given message
+func expectReflectionError(t *testing.T, err error, class, message string) {
+	t.Helper()
+	thrown, ok := err.(*ThrowableError)
+	if !ok || thrown.Object.ClassEntry.Name != class || throwableProperty(thrown.Object, "message").ToString() != message {
+		t.Errorf("Expected %s %q, got %v", class, message, err)
+	}
+}
+
+// counterClasses declares
+//
+//	abstract class Base { abstract function run(); }
+//	/** A counter */
+//	class Counter extends Base {
+//	    const START = 1;
+//	    public static $instances = 0;
+//	    /** Current count */
+//	    private int $count;
+//	    function __construct(int $count = 0) {...}
+//	    private function add(int $n, ?string ...$labels): int {...}
+//	    static function create() {...}
+//	    function run() {}
+//	}
+func counterClasses(vm *VM) (*types.ClassEntry, *types.ClassEntry) {
+	base := types.NewClassEntry("Base")
+	base.IsAbstract = true
+	base.Methods["run"] = &types.MethodDef{Name: "run", Visibility: types.VisibilityPublic, IsAbstract: true, DeclaringClass: "Base"}
+	vm.classes["Base"] = base
+
+	counter := types.NewClassEntry("Counter")
+	counter.InheritFrom(base)
+	counter.DocComment = "/** A counter */"
+	counter.Constants["START"] = &types.ClassConstant{Name: "START", Value: types.NewInt(1), Visibility: types.VisibilityPublic}
+	counter.Properties["instances"] = &types.PropertyDef{Name: "instances", Visibility: types.VisibilityPublic, IsStatic: true, DeclaringClass: "Counter"}
+	counter.SetStaticProperty("instances", types.NewInt(0))
+	counter.Properties["count"] = &types.PropertyDef{Name: "count", Visibility: types.VisibilityPrivate, Type: "int",
+		DeclaringClass: "Counter", DocComment: "/** Current count */"}
+
+	addNativeMethod(counter, "__construct", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		count := types.NewInt(0)
+		if len(args) > 0 && args[0] != nil {
+			count = args[0]
+		}
+		this.Properties["count"] = &types.Property{Value: count, Visibility: types.VisibilityPrivate}
+		return nil, nil
+	})
+	counter.Constructor = counter.Methods["__construct"]
+	counter.Constructor.Parameters = []*types.ParameterDef{{Name: "count", Type: "int", HasDefault: true, Default: types.NewInt(0)}}
+
+	add := addNativeMethod(counter, "add", 2, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		count := this.Properties["count"].Value.ToInt() + args[0].ToInt()
+		this.Properties["count"].Value = types.NewInt(count)
+		return types.NewInt(count), nil
+	})
+	add.Visibility = types.VisibilityPrivate
+	add.ReturnType = "int"
+	add.Parameters = []*types.ParameterDef{{Name: "n", Type: "int"}, {Name: "labels", Type: "?string", IsVariadic: true}}
+
+	addNativeMethod(counter, "create", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return types.NewString("created"), nil
+	}).IsStatic = true
+	addNativeMethod(counter, "run", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return nil, nil
+	})
+	vm.classes["Counter"] = counter
+	return base, counter
+}
+
+func TestReflectionClass_Members(t *testing.T) {
+	vm := New()
+	counterClasses(vm)
+	class := reflectClass(t, vm, "Counter")
+
+	if got := callReflectionMethod(t, vm, class, "getDocComment").ToString(); got != "/** A counter */" {
+		t.Errorf("getDocComment() = %q", got)
+	}
+	if parent := callReflectionMethod(t, vm, class, "getParentClass"); parent.Type() != types.TypeObject ||
+		!callReflectionMethod(t, vm, parent, "isAbstract").ToBool() {
+		t.Errorf("getParentClass() = %v", parent)
+	}
+	if !callReflectionMethod(t, vm, class, "isSubclassOf", types.NewString("Base")).ToBool() {
+		t.Error("Expected Counter to be a subclass of Base")
+	}
+	if got := callReflectionMethod(t, vm, class, "getConstant", types.NewString("START")).ToInt(); got != 1 {
+		t.Errorf("getConstant('START') = %d", got)
+	}
+
+	methods := callReflectionMethod(t, vm, class, "getMethods").ToArray()
+	if methods.Len() != 4 {
+		t.Fatalf("Expected 4 methods, got %d", methods.Len())
+	}
+	private := types.NewInt(reflection.IS_PRIVATE)
+	if got := callReflectionMethod(t, vm, class, "getMethods", private).ToArray().Len(); got != 1 {
+		t.Errorf("getMethods(IS_PRIVATE) returned %d methods", got)
+	}
+	if got := callReflectionMethod(t, vm, class, "getProperties").ToArray().Len(); got != 2 {
+		t.Errorf("getProperties() returned %d properties", got)
+	}
+	if got := callReflectionMethod(t, vm, class, "getStaticPropertyValue", types.NewString("instances")).ToInt(); got != 0 {
+		t.Errorf("getStaticPropertyValue('instances') = %d", got)
+	}
+
+	_, err := vm.CallCallable(callableArray(class, "getMethod"), []*types.Value{types.NewString("nope")})
+	expectReflectionError(t, err, "ReflectionException", "Method Counter::nope() does not exist")
+	_, err = vm.CallCallable(callableArray(class, "getProperty"), []*types.Value{types.NewString("nope")})
+	expectReflectionError(t, err, "ReflectionException", "Property Counter::$nope does not exist")
+}
+
+func TestReflectionClass_NewInstance(t *testing.T) {
+	vm := New()
+	counterClasses(vm)
+	class := reflectClass(t, vm, "Counter")
+
+	args := types.NewEmptyArray()
+	args.Set(types.NewString("count"), types.NewInt(5))
+	obj := callReflectionMethod(t, vm, class, "newInstanceArgs", types.NewArray(args)).ToObject()
+	if got := obj.Properties["count"].Value.ToInt(); got != 5 {
+		t.Errorf("newInstanceArgs(['count' => 5]) set count to %d", got)
+	}
+	obj = callReflectionMethod(t, vm, class, "newInstanceWithoutConstructor").ToObject()
+	if obj.Properties["count"].Value != nil {
+		t.Error("Expected newInstanceWithoutConstructor() not to call the constructor")
+	}
+
+	_, err := vm.CallCallable(callableArray(reflectClass(t, vm, "Base"), "newInstance"), nil)
+	expectReflectionError(t, err, "Error", "Cannot instantiate abstract class Base")
+
+	vm.classes["Plain"] = types.NewClassEntry("Plain")
+	_, err = vm.CallCallable(callableArray(reflectClass(t, vm, "Plain"), "newInstance"), []*types.Value{types.NewInt(1)})
+	expectReflectionError(t, err, "ReflectionException", "Class Plain does not have a constructor, so you cannot pass any constructor arguments")
+}
+
+func TestReflectionMethod(t *testing.T) {
+	vm := New()
+	counterClasses(vm)
+	counter := callReflectionMethod(t, vm, reflectClass(t, vm, "Counter"), "newInstance", types.NewInt(2))
+
+	method := reflectionOf(t, vm, "ReflectionMethod", types.NewString("Counter::add"))
+	if !callReflectionMethod(t, vm, method, "isPrivate").ToBool() {
+		t.Error("Expected add() to be private")
+	}
+	callReflectionMethod(t, vm, method, "setAccessible", types.NewBool(true))
+	if got := callReflectionMethod(t, vm, method, "invoke", counter, types.NewInt(3)).ToInt(); got != 5 {
+		t.Errorf("invoke($counter, 3) = %d", got)
+	}
+	args := types.NewEmptyArray()
+	args.Set(types.NewString("n"), types.NewInt(10))
+	if got := callReflectionMethod(t, vm, method, "invokeArgs", counter, types.NewArray(args)).ToInt(); got != 15 {
+		t.Errorf("invokeArgs($counter, ['n' => 10]) = %d", got)
+	}
+	if got := callReflectionMethod(t, vm, method, "getNumberOfRequiredParameters").ToInt(); got != 1 {
+		t.Errorf("getNumberOfRequiredParameters() = %d", got)
+	}
+	returnType := callReflectionMethod(t, vm, method, "getReturnType")
+	if got := callReflectionMethod(t, vm, returnType, "getName").ToString(); got != "int" {
+		t.Errorf("getReturnType()->getName() = %q", got)
+	}
+
+	static := reflectionOf(t, vm, "ReflectionMethod", types.NewString("Counter"), types.NewString("CREATE"))
+	if got := callReflectionMethod(t, vm, static, "invoke", types.NewNull()).ToString(); got != "created" {
+		t.Errorf("invoke(null) = %q", got)
+	}
+
+	_, err := vm.CallCallable(callableArray(method, "invoke"), []*types.Value{types.NewNull()})
+	expectReflectionError(t, err, "ReflectionException", "Trying to invoke non static method Counter::add() without an object")
+	abstract := reflectionOf(t, vm, "ReflectionMethod", types.NewString("Base"), types.NewString("run"))
+	_, err = vm.CallCallable(callableArray(abstract, "invoke"), []*types.Value{counter})
+	expectReflectionError(t, err, "ReflectionException", "Trying to invoke abstract method Base::run()")
+	vm.classes["Plain"] = types.NewClassEntry("Plain")
+	other := types.NewObject(types.NewObjectFromClass(vm.classes["Plain"]))
+	_, err = vm.CallCallable(callableArray(method, "invoke"), []*types.Value{other, types.NewInt(1)})
+	expectReflectionError(t, err, "ReflectionException", "Given object is not an instance of the class this method was declared in")
+}
+
+func TestReflectionParameter(t *testing.T) {
+	vm := New()
+	counterClasses(vm)
+	method := reflectionOf(t, vm, "ReflectionMethod", types.NewString("Counter"), types.NewString("add"))
+	params := callReflectionMethod(t, vm, method, "getParameters").ToArray()
+	if params.Len() != 2 {
+		t.Fatalf("Expected 2 parameters, got %d", params.Len())
+	}
+
+	labels, _ := params.Get(types.NewInt(1))
+	if got := callReflectionMethod(t, vm, labels, "getName").ToString(); got != "labels" {
+		t.Errorf("getName() = %q", got)
+	}
+	if !callReflectionMethod(t, vm, labels, "isVariadic").ToBool() || !callReflectionMethod(t, vm, labels, "isOptional").ToBool() {
+		t.Error("Expected $labels to be variadic and optional")
+	}
+	labelType := callReflectionMethod(t, vm, labels, "getType")
+	if got := callReflectionMethod(t, vm, labelType, "__toString").ToString(); got != "?string" {
+		t.Errorf("getType() = %q", got)
+	}
+	if !callReflectionMethod(t, vm, labelType, "allowsNull").ToBool() || !callReflectionMethod(t, vm, labelType, "isBuiltin").ToBool() {
+		t.Error("Expected a nullable builtin type")
+	}
+	_, err := vm.CallCallable(callableArray(labels, "getDefaultValue"), nil)
+	expectReflectionError(t, err, "ReflectionException", "Internal error: Failed to retrieve the default value")
+
+	count := reflectionOf(t, vm, "ReflectionParameter", callableArray(types.NewString("Counter"), "__construct"), types.NewString("count"))
+	if got := callReflectionMethod(t, vm, count, "getDefaultValue").ToInt(); got != 0 {
+		t.Errorf("getDefaultValue() = %d", got)
+	}
+	if got := callReflectionMethod(t, vm, callReflectionMethod(t, vm, count, "getDeclaringClass"), "getName").ToString(); got != "Counter" {
+		t.Errorf("getDeclaringClass() = %q", got)
+	}
+}
+
+func TestReflectionType_UnionAndIntersection(t *testing.T) {
+	vm := New()
+	tests := []struct {
+		decl  string
+		class string
+		str   string
+		types int
+	}{
+		{"int|null", "ReflectionNamedType", "?int", 0},
+		{"int|string", "ReflectionUnionType", "int|string", 2},
+		{"Countable&Traversable", "ReflectionIntersectionType", "Countable&Traversable", 2},
+		{"(A&B)|null", "ReflectionUnionType", "(A&B)|null", 2},
+	}
+
+	for _, tt := range tests {
+		reflected := vm.newReflectionType(tt.decl)
+		if got := reflected.ToObject().ClassEntry.Name; got != tt.class {
+			t.Errorf("%s: expected %s, got %s", tt.decl, tt.class, got)
+			continue
+		}
+		if got := callReflectionMethod(t, vm, reflected, "__toString").ToString(); got != tt.str {
+			t.Errorf("%s: __toString() = %q", tt.decl, got)
+		}
+		if tt.types == 0 {
+			continue
+		}
+		members := callReflectionMethod(t, vm, reflected, "getTypes").ToArray()
+		if members.Len() != tt.types {
+			t.Errorf("%s: getTypes() returned %d types", tt.decl, members.Len())
+		}
+	}
+
+	dnf := callReflectionMethod(t, vm, vm.newReflectionType("(A&B)|null"), "getTypes").ToArray()
+	first, _ := dnf.Get(types.NewInt(0))
+	if got := first.ToObject().ClassEntry.Name; got != "ReflectionIntersectionType" {
+		t.Errorf("Expected the first DNF member to be an intersection, got %s", got)
+	}
+	if !callReflectionMethod(t, vm, vm.newReflectionType("(A&B)|null"), "allowsNull").ToBool() {
+		t.Error("(A&B)|null should allow null")
+	}
+}
+
+func TestReflectionProperty(t *testing.T) {
+	vm := New()
+	counterClasses(vm)
+	counter := callReflectionMethod(t, vm, reflectClass(t, vm, "Counter"), "newInstance", types.NewInt(7))
+
+	count := reflectionOf(t, vm, "ReflectionProperty", types.NewString("Counter"), types.NewString("count"))
+	if got := callReflectionMethod(t, vm, count, "getDocComment").ToString(); got != "/** Current count */" {
+		t.Errorf("getDocComment() = %q", got)
+	}
+	callReflectionMethod(t, vm, count, "setAccessible", types.NewBool(true))
+	if got := callReflectionMethod(t, vm, count, "getValue", counter).ToInt(); got != 7 {
+		t.Errorf("getValue() = %d", got)
+	}
+	callReflectionMethod(t, vm, count, "setValue", counter, types.NewInt(9))
+	if got := counter.ToObject().Properties["count"].Value.ToInt(); got != 9 {
+		t.Errorf("setValue() set count to %d", got)
+	}
+	modifiers := callReflectionMethod(t, vm, count, "getModifiers")
+	names, err := vm.CallCallable(types.NewString("Reflection::getModifierNames"), []*types.Value{modifiers})
+	if first, _ := names.ToArray().Get(types.NewInt(0)); err != nil || names.ToArray().Len() != 1 || first.ToString() != "private" {
+		t.Errorf("Reflection::getModifierNames() = %v, %v", names, err)
+	}
+
+	blank := callReflectionMethod(t, vm, reflectClass(t, vm, "Counter"), "newInstanceWithoutConstructor")
+	if callReflectionMethod(t, vm, count, "isInitialized", blank).ToBool() {
+		t.Error("Expected an uninitialized property")
+	}
+	_, err = vm.CallCallable(callableArray(count, "getValue"), []*types.Value{blank})
+	expectReflectionError(t, err, "Error", "Typed property Counter::$count must not be accessed before initialization")
+	_, err = vm.CallCallable(callableArray(count, "getValue"), nil)
+	expectReflectionError(t, err, "TypeError", "ReflectionProperty::getValue(): Argument #1 ($object) must be provided for instance properties")
+
+	instances := reflectionOf(t, vm, "ReflectionProperty", types.NewString("Counter"), types.NewString("instances"))
+	callReflectionMethod(t, vm, instances, "setValue", types.NewInt(3))
+	if got := callReflectionMethod(t, vm, instances, "getValue").ToInt(); got != 3 {
+		t.Errorf("getValue() of a static property = %d", got)
+	}
+}
+
+func TestReflectionFunction(t *testing.T) {
+	vm := New()
+	vm.functions["greet"] = &CompiledFunction{
+		Name: "greet", NumParams: 1, ReturnType: "string", DocComment: "/** Says hello */",
+		Parameters: []*types.ParameterDef{{Name: "name", Type: "string"}},
+	}
+
+	greet := reflectionOf(t, vm, "ReflectionFunction", types.NewString("greet"))
+	if callReflectionMethod(t, vm, greet, "isInternal").ToBool() {
+		t.Error("Expected a user-defined function")
+	}
+	if got := callReflectionMethod(t, vm, greet, "getDocComment").ToString(); got != "/** Says hello */" {
+		t.Errorf("getDocComment() = %q", got)
+	}
+	if got := callReflectionMethod(t, vm, greet, "getNumberOfParameters").ToInt(); got != 1 {
+		t.Errorf("getNumberOfParameters() = %d", got)
+	}
+
+	strlen := reflectionOf(t, vm, "ReflectionFunction", types.NewString("mb_strlen"))
+	if !callReflectionMethod(t, vm, strlen, "isInternal").ToBool() {
+		t.Error("Expected mb_strlen() to be internal")
+	}
+	if got := callReflectionMethod(t, vm, strlen, "invoke", types.NewString("abcd")).ToInt(); got != 4 {
+		t.Errorf("invoke('abcd') = %d", got)
+	}
+	args := types.NewEmptyArray()
+	args.Append(types.NewString("ab"))
+	if got := callReflectionMethod(t, vm, strlen, "invokeArgs", types.NewArray(args)).ToInt(); got != 2 {
+		t.Errorf("invokeArgs(['ab']) = %d", got)
+	}
+	closure := callReflectionMethod(t, vm, strlen, "getClosure")
+	if got, _ := vm.CallCallable(closure, []*types.Value{types.NewString("abc")}); got.ToInt() != 3 {
+		t.Errorf("getClosure()('abc') = %v", got)
+	}
+
+	_, err := vm.instantiate(vm.classes["ReflectionFunction"], &CallParams{params: []*types.Value{types.NewString("nope")}})
+	expectReflectionError(t, err, "ReflectionException", "Function nope() does not exist")
+}
@@ -0,0 +1,52 @@
+package vm
+
+import (
+	"fmt"
+
+	varfuncs "github.com/krizos/php-go/pkg/stdlib/var"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Resource Builtins
+// ============================================================================
+
+// registerResourceBuiltins registers the functions that inspect resources
+func (vm *VM) registerResourceBuiltins() {
+	vm.RegisterBuiltin("is_resource", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		if len(args) < 1 {
+			return nil, fmt.Errorf("is_resource() expects exactly 1 argument, 0 given")
+		}
+		return varfuncs.IsResource(args[0].Deref()), nil
+	})
+	vm.RegisterBuiltin("get_resource_type", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		res, err := vm.resourceArg("get_resource_type", args)
+		if err != nil {
+			return nil, err
+		}
+		return varfuncs.GetResourceType(res), nil
+	})
+	vm.RegisterBuiltin("get_resource_id", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		res, err := vm.resourceArg("get_resource_id", args)
+		if err != nil {
+			return nil, err
+		}
+		return varfuncs.GetResourceID(res), nil
+	})
+	vm.RegisterBuiltin("get_resources", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return varfuncs.GetResources(derefArgs(args)...), nil
+	})
+}
+
+// resourceArg returns the $resource argument, throwing a TypeError for
+// anything that is not a resource (open or closed)
+func (vm *VM) resourceArg(function string, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("%s() expects exactly 1 argument, 0 given", function)
+	}
+	value := args[0].Deref()
+	if !value.IsResource() {
+		return nil, vm.ThrowError("TypeError", "%s(): Argument #1 ($resource) must be of type resource, %s given", function, value.TypeString())
+	}
+	return value, nil
+}
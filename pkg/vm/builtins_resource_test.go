@@ -0,0 +1,47 @@
+package vm
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestResourceBuiltins_StreamLifecycle(t *testing.T) {
+	vm := New()
+	path := types.NewString(filepath.Join(t.TempDir(), "res.txt"))
+
+	handle, err := vm.CallCallable(types.NewString("fopen"), []*types.Value{path, types.NewString("w")})
+	if err != nil || !handle.IsResource() {
+		t.Fatalf("fopen() = %v, %v", handle, err)
+	}
+
+	expect := func(name, want string) {
+		t.Helper()
+		result, err := vm.CallCallable(types.NewString(name), []*types.Value{handle})
+		if err != nil {
+			t.Fatalf("%s() failed: %v", name, err)
+		}
+		if result.ToString() != want {
+			t.Errorf("%s() = %q, want %q", name, result.ToString(), want)
+		}
+	}
+
+	expect("is_resource", "1")
+	expect("get_resource_type", "stream")
+	expect("gettype", "resource")
+	vm.CallCallable(types.NewString("fclose"), []*types.Value{handle})
+	expect("is_resource", "")
+	expect("get_resource_type", "Unknown")
+	expect("gettype", "resource (closed)")
+}
+
+func TestGetResourceType_RequiresResource(t *testing.T) {
+	vm := New()
+
+	_, err := vm.CallCallable(types.NewString("get_resource_type"), []*types.Value{types.NewString("x")})
+	throwable, ok := err.(*ThrowableError)
+	if !ok || throwable.Object.ClassEntry.Name != "TypeError" {
+		t.Fatalf("Expected a TypeError, got %v", err)
+	}
+}
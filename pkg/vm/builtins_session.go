@@ -0,0 +1,612 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/stdlib/filter"
+	"github.com/krizos/php-go/pkg/stdlib/session"
+	varfuncs "github.com/krizos/php-go/pkg/stdlib/var"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Session Builtins
+// ============================================================================
+
+// sessionState returns the session of the request, created on first use
+func (vm *VM) sessionState() *session.Session {
+	if vm.session == nil {
+		vm.session = session.New()
+		vm.applySessionConfig(vm.session)
+		vm.session.Serializer = vm.serializer()
+		vm.session.SendCookie = func(header string) {
+			vm.response.Add(header, false, 0)
+		}
+	}
+	return vm.session
+}
+
+// sessionVars returns the array of $_SESSION, replacing any other value
+// the script assigned to it
+func (vm *VM) sessionVars() *types.Array {
+	if vars, ok := vm.GetGlobal("_SESSION"); ok && vars.IsArray() {
+		return vars.ToArray()
+	}
+	vars := types.NewEmptyArray()
+	vm.SetGlobal("_SESSION", types.NewArray(vars))
+	return vars
+}
+
+// sessionCookie returns the session ID the request's cookie carries
+func (vm *VM) sessionCookie(name string) string {
+	cookies := vm.requestInput[filter.INPUT_COOKIE]
+	if cookies == nil {
+		return ""
+	}
+	if id, ok := cookies.Get(types.NewString(name)); ok && id.IsString() {
+		return id.ToString()
+	}
+	return ""
+}
+
+// closeSession writes and closes a session still active at the end of
+// the request
+func (vm *VM) closeSession() error {
+	if vm.session == nil || vm.session.Status != session.PHP_SESSION_ACTIVE {
+		return nil
+	}
+	vm.session.Warning = func(message string) { vm.warning("Unknown: %s", message) }
+	_, err := vm.session.WriteClose(vm.sessionVars())
+	return vm.sessionError(err)
+}
+
+// sessionError converts session failures into PHP exceptions
+func (vm *VM) sessionError(err error) error {
+	if e, ok := err.(*session.Error); ok {
+		return vm.ThrowError(e.Class, "%s", e.Message)
+	}
+	return err
+}
+
+// ============================================================================
+// Object Serialization
+// ============================================================================
+
+// serializer returns the serializer of the VM's objects: __serialize()
+// and __sleep() choose what is written, __unserialize() and __wakeup()
+// restore it, and classes are autoloaded
+func (vm *VM) serializer() *varfuncs.Serializer {
+	return &varfuncs.Serializer{
+		Properties:  vm.serializeProperties,
+		Instantiate: vm.unserializeObject,
+	}
+}
+
+// serializeProperties returns the entries written for an object
+func (vm *VM) serializeProperties(obj *types.Object) (*types.Array, error) {
+	if method := magicMethodOf(obj.ClassEntry, "__serialize"); method != nil {
+		result, err := vm.invokeTarget(vm.methodTarget(obj.ClassEntry, obj, method), nil)
+		if err != nil {
+			return nil, err
+		}
+		if result = result.Deref(); !result.IsArray() {
+			return nil, vm.ThrowError("TypeError", "%s::__serialize() must return an array", obj.ClassName)
+		}
+		return result.ToArray(), nil
+	}
+
+	props := varfuncs.ObjectProperties(obj)
+	method := magicMethodOf(obj.ClassEntry, "__sleep")
+	if method == nil {
+		return props, nil
+	}
+	result, err := vm.invokeTarget(vm.methodTarget(obj.ClassEntry, obj, method), nil)
+	if err != nil {
+		return nil, err
+	}
+	selected := types.NewEmptyArray()
+	if result = result.Deref(); !result.IsArray() {
+		vm.warning("serialize(): __sleep should return an array only containing the names of instance-variables to serialize")
+		return selected, nil
+	}
+	result.ToArray().Each(func(_, name *types.Value) bool {
+		n := name.ToString()
+		for _, key := range []string{n, "\x00*\x00" + n, "\x00" + obj.ClassName + "\x00" + n} {
+			if value, ok := props.Get(types.NewString(key)); ok {
+				selected.Set(types.NewString(key), value)
+				return true
+			}
+		}
+		vm.warning("serialize(): \"%s\" returned as member variable from __sleep() but does not exist", n)
+		selected.Set(types.NewString(n), types.NewNull())
+		return true
+	})
+	return selected, nil
+}
+
+// unserializeObject creates an object read from the serialize() format.
+// Objects of unknown classes become __PHP_Incomplete_Class objects.
+func (vm *VM) unserializeObject(className string, data *types.Array) (*types.Value, error) {
+	class, ok, err := vm.loadClass(className)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		incomplete, _ := vm.lookupClass("__PHP_Incomplete_Class")
+		obj := types.NewObjectFromClass(incomplete)
+		obj.AddProperty("__PHP_Incomplete_Class_Name", &types.Property{Value: types.NewString(className)})
+		varfuncs.RestoreProperties(obj, data)
+		return types.NewObject(obj), nil
+	}
+
+	obj, err := vm.newInstance(class)
+	if err != nil {
+		return nil, err
+	}
+	if method := magicMethodOf(class, "__unserialize"); method != nil {
+		if _, err := vm.invokeTarget(vm.methodTarget(class, obj, method), []*types.Value{types.NewArray(data)}); err != nil {
+			return nil, err
+		}
+		return types.NewObject(obj), nil
+	}
+	varfuncs.RestoreProperties(obj, data)
+	if method := magicMethodOf(class, "__wakeup"); method != nil {
+		if _, err := vm.invokeTarget(vm.methodTarget(class, obj, method), nil); err != nil {
+			return nil, err
+		}
+	}
+	return types.NewObject(obj), nil
+}
+
+// ============================================================================
+// User Save Handlers
+// ============================================================================
+
+// userSessionHandler stores sessions through the callbacks of
+// session_set_save_handler(), or the methods of a SessionHandlerInterface
+// object
+type userSessionHandler struct {
+	vm                                   *VM
+	open, close, read, write, destroy    *types.Value
+	gc, createSid, validateSid, updateTs *types.Value // Optional ones are nil
+}
+
+// call calls a callback that returns a bool, failing for false
+func (h *userSessionHandler) call(callback *types.Value, args ...*types.Value) error {
+	result, err := h.vm.CallCallable(callback, args)
+	if err != nil {
+		return err
+	}
+	if result = result.Deref(); !result.IsBool() {
+		return h.vm.ThrowError("TypeError", "Session callback must have a return value of type bool, %s returned", result.TypeName())
+	}
+	if !result.ToBool() {
+		return session.ErrFailed
+	}
+	return nil
+}
+
+func (h *userSessionHandler) Open(savePath, name string) error {
+	return h.call(h.open, types.NewString(savePath), types.NewString(name))
+}
+
+func (h *userSessionHandler) Close() error {
+	return h.call(h.close)
+}
+
+func (h *userSessionHandler) Read(id string) (string, error) {
+	result, err := h.vm.CallCallable(h.read, []*types.Value{types.NewString(id)})
+	if err != nil {
+		return "", err
+	}
+	if result = result.Deref(); result.IsBool() && !result.ToBool() {
+		return "", session.ErrFailed
+	}
+	return result.ToString(), nil
+}
+
+func (h *userSessionHandler) Write(id, data string) error {
+	return h.call(h.write, types.NewString(id), types.NewString(data))
+}
+
+func (h *userSessionHandler) Destroy(id string) error {
+	return h.call(h.destroy, types.NewString(id))
+}
+
+func (h *userSessionHandler) GC(maxLifetime int64) (int64, error) {
+	result, err := h.vm.CallCallable(h.gc, []*types.Value{types.NewInt(maxLifetime)})
+	if err != nil {
+		return 0, err
+	}
+	if result = result.Deref(); result.IsBool() && !result.ToBool() {
+		return 0, session.ErrFailed
+	}
+	return result.ToInt(), nil
+}
+
+// CreateID calls the create_sid callback, if there is one
+func (h *userSessionHandler) CreateID() (string, error) {
+	if h.createSid == nil {
+		return session.RandomID()
+	}
+	result, err := h.vm.CallCallable(h.createSid, nil)
+	if err != nil {
+		return "", err
+	}
+	if result = result.Deref(); !result.IsString() {
+		return "", session.ErrFailed
+	}
+	return result.ToString(), nil
+}
+
+// UpdateTimestamp calls the update_timestamp callback, writing the data
+// when there is none
+func (h *userSessionHandler) UpdateTimestamp(id, data string) error {
+	if h.updateTs == nil {
+		return h.Write(id, data)
+	}
+	return h.call(h.updateTs, types.NewString(id), types.NewString(data))
+}
+
+// objectSessionHandler returns the handler calling the methods of a
+// SessionHandlerInterface object
+func (vm *VM) objectSessionHandler(obj *types.Object) *userSessionHandler {
+	method := func(name string) *types.Value {
+		return types.NewArray(types.NewArrayFromSlice([]*types.Value{types.NewObject(obj), types.NewString(name)}))
+	}
+	h := &userSessionHandler{
+		vm: vm, open: method("open"), close: method("close"), read: method("read"),
+		write: method("write"), destroy: method("destroy"), gc: method("gc"),
+	}
+	if vm.isInstanceOf(obj.ClassEntry, "SessionIdInterface") {
+		h.createSid = method("create_sid")
+	}
+	if vm.isInstanceOf(obj.ClassEntry, "SessionUpdateTimestampHandlerInterface") {
+		h.validateSid, h.updateTs = method("validateId"), method("updateTimestamp")
+	}
+	return h
+}
+
+// sessionHandlerCallbacks names the callbacks of session_set_save_handler()
+var sessionHandlerCallbacks = []string{"open", "close", "read", "write", "destroy", "gc", "create_sid", "validate_sid", "update_timestamp"}
+
+// session_set_save_handler(SessionHandlerInterface $sessionhandler, bool $register_shutdown = true): bool
+// session_set_save_handler(callable $open, callable $close, callable $read, callable $write,
+// callable $destroy, callable $gc, ?callable $create_sid = null, ...): bool
+func builtinSessionSetSaveHandler(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+	if s.Status == session.PHP_SESSION_ACTIVE {
+		vm.warning("session_set_save_handler(): Session save handler cannot be changed when a session is active")
+		return types.NewBool(false), nil
+	}
+	if vm.headersSent() {
+		vm.warning("session_set_save_handler(): Session save handler cannot be changed after headers have already been sent")
+		return types.NewBool(false), nil
+	}
+
+	if len(a) <= 2 {
+		if !a[0].IsObject() || !vm.isInstanceOf(a[0].ToObject().ClassEntry, "SessionHandlerInterface") {
+			return nil, vm.ThrowError("TypeError", "session_set_save_handler(): Argument #1 ($open) must be of type SessionHandlerInterface, %s given", a[0].TypeName())
+		}
+		s.Handler = vm.objectSessionHandler(a[0].ToObject())
+		if len(a) < 2 || a[1].ToBool() {
+			vm.shutdownFunctions = append(vm.shutdownFunctions, shutdownFunction{callback: types.NewString("session_write_close")})
+		}
+		return types.NewBool(true), nil
+	}
+
+	if len(a) < 6 {
+		return nil, fmt.Errorf("session_set_save_handler() expects at least 6 arguments, %d given", len(a))
+	}
+	callbacks := make([]*types.Value, len(sessionHandlerCallbacks))
+	for i, callback := range a {
+		if i >= len(callbacks) || (i >= 6 && callback.IsNull()) {
+			continue
+		}
+		if !vm.IsCallable(callback) {
+			return nil, vm.ThrowError("TypeError", "session_set_save_handler(): Argument #%d ($%s) must be a valid callback, %s given",
+				i+1, sessionHandlerCallbacks[i], callback.TypeName())
+		}
+		callbacks[i] = callback
+	}
+	s.Handler = &userSessionHandler{
+		vm: vm, open: callbacks[0], close: callbacks[1], read: callbacks[2], write: callbacks[3],
+		destroy: callbacks[4], gc: callbacks[5], createSid: callbacks[6], validateSid: callbacks[7], updateTs: callbacks[8],
+	}
+	return types.NewBool(true), nil
+}
+
+// ============================================================================
+// Session Functions
+// ============================================================================
+
+// sessionFunction is a session builtin with its minimum argument count
+type sessionFunction struct {
+	required int
+	call     func(vm *VM, s *session.Session, args []*types.Value) (*types.Value, error)
+}
+
+// sessionBool converts the result of a session operation
+func sessionBool(ok bool, err error) (*types.Value, error) {
+	if err != nil {
+		return nil, err
+	}
+	return types.NewBool(ok), nil
+}
+
+// sessionSetting returns the old value of a setting and changes it to
+// the argument, unless the session is active or headers were sent
+func sessionSetting(vm *VM, s *session.Session, a []*types.Value, function, what string, field *string) (*types.Value, error) {
+	old := *field
+	if len(a) == 0 || a[0].IsNull() {
+		return types.NewString(old), nil
+	}
+	if s.Status == session.PHP_SESSION_ACTIVE {
+		vm.warning("%s(): %s cannot be changed when a session is active", function, what)
+		return types.NewBool(false), nil
+	}
+	if vm.headersSent() {
+		vm.warning("%s(): %s cannot be changed after headers have already been sent", function, what)
+		return types.NewBool(false), nil
+	}
+	*field = a[0].ToString()
+	return types.NewString(old), nil
+}
+
+// sessionFunctions maps the session functions to their implementations
+var sessionFunctions = map[string]sessionFunction{
+	"session_start": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		if s.Status == session.PHP_SESSION_ACTIVE {
+			vm.notice("session_start(): Ignoring session_start() because a session is already active")
+			return types.NewBool(true), nil
+		}
+		if vm.headersSent() {
+			vm.warning("session_start(): Session cannot be started after headers have already been sent")
+			return types.NewBool(false), nil
+		}
+		readAndClose := false
+		if len(a) > 0 {
+			if !a[0].IsArray() {
+				return nil, vm.ThrowError("TypeError", "session_start(): Argument #1 ($options) must be of type array, %s given", a[0].TypeName())
+			}
+			a[0].ToArray().Each(func(key, value *types.Value) bool {
+				value = value.Deref()
+				if name := key.ToString(); name == "read_and_close" {
+					readAndClose = value.ToBool()
+				} else if !s.SetOption(name, value) {
+					vm.warning("session_start(): Setting option \"%s\" failed", name)
+				}
+				return true
+			})
+		}
+
+		data, ok, err := s.Start(vm.sessionCookie(s.Name))
+		if !ok || err != nil {
+			return sessionBool(false, err)
+		}
+		vm.SetGlobal("_SESSION", types.NewArray(data))
+		if readAndClose {
+			return sessionBool(s.Abort())
+		}
+		return types.NewBool(true), nil
+	}},
+	"session_id": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		return sessionSetting(vm, s, a, "session_id", "Session ID", &s.ID)
+	}},
+	"session_name": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		return sessionSetting(vm, s, a, "session_name", "Session name", &s.Name)
+	}},
+	"session_save_path": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		return sessionSetting(vm, s, a, "session_save_path", "Session save path", &s.SavePath)
+	}},
+	"session_status": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		return types.NewInt(int64(s.Status)), nil
+	}},
+	"session_write_close": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		return sessionBool(s.WriteClose(vm.sessionVars()))
+	}},
+	"session_commit": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		return sessionBool(s.WriteClose(vm.sessionVars()))
+	}},
+	"session_abort": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		return sessionBool(s.Abort())
+	}},
+	"session_reset": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		data, ok, err := s.Reset()
+		if ok && err == nil {
+			vm.SetGlobal("_SESSION", types.NewArray(data))
+		}
+		return sessionBool(ok, err)
+	}},
+	"session_unset": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		if s.Status != session.PHP_SESSION_ACTIVE {
+			return types.NewBool(false), nil
+		}
+		vm.SetGlobal("_SESSION", types.NewArray(types.NewEmptyArray()))
+		return types.NewBool(true), nil
+	}},
+	"session_destroy": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		return sessionBool(s.Destroy())
+	}},
+	"session_regenerate_id": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		if s.Status == session.PHP_SESSION_ACTIVE && vm.headersSent() {
+			vm.warning("session_regenerate_id(): Session ID cannot be regenerated after headers have already been sent")
+			return types.NewBool(false), nil
+		}
+		deleteOld := len(a) > 0 && a[0].ToBool()
+		return sessionBool(s.RegenerateID(deleteOld, vm.sessionVars()))
+	}},
+	"session_create_id": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		prefix := ""
+		if len(a) > 0 {
+			prefix = a[0].ToString()
+		}
+		id, ok, err := s.CreateID(prefix)
+		if !ok || err != nil {
+			return sessionBool(false, err)
+		}
+		return types.NewString(id), nil
+	}},
+	"session_gc": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		return s.GC()
+	}},
+	"session_encode": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		if s.Status != session.PHP_SESSION_ACTIVE {
+			vm.warning("session_encode(): Cannot encode non-existent session")
+			return types.NewBool(false), nil
+		}
+		data, ok, err := s.Encode(vm.sessionVars())
+		if !ok || err != nil {
+			return sessionBool(false, err)
+		}
+		return types.NewString(data), nil
+	}},
+	"session_decode": {1, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		if s.Status != session.PHP_SESSION_ACTIVE {
+			vm.warning("session_decode(): Session data cannot be decoded when there is no active session")
+			return types.NewBool(false), nil
+		}
+		return sessionBool(s.Decode(a[0].ToString(), vm.sessionVars()))
+	}},
+	"session_get_cookie_params": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		return types.NewArray(s.Cookie.Array()), nil
+	}},
+	"session_set_cookie_params": {1, builtinSessionSetCookieParams},
+	"session_set_save_handler":  {1, builtinSessionSetSaveHandler},
+	"session_register_shutdown": {0, func(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+		vm.shutdownFunctions = append(vm.shutdownFunctions, shutdownFunction{callback: types.NewString("session_write_close")})
+		return types.NewNull(), nil
+	}},
+}
+
+// session_set_cookie_params(array|int $lifetime_or_options, ?string $path = null,
+// ?string $domain = null, ?bool $secure = null, ?bool $httponly = null): bool
+func builtinSessionSetCookieParams(vm *VM, s *session.Session, a []*types.Value) (*types.Value, error) {
+	if s.Status == session.PHP_SESSION_ACTIVE {
+		vm.warning("session_set_cookie_params(): Session cookie parameters cannot be changed when a session is active")
+		return types.NewBool(false), nil
+	}
+	if vm.headersSent() {
+		vm.warning("session_set_cookie_params(): Session cookie parameters cannot be changed after headers have already been sent")
+		return types.NewBool(false), nil
+	}
+
+	params := s.Cookie
+	if !a[0].IsArray() {
+		params.Lifetime = a[0].ToInt()
+		setters := []func(v *types.Value){
+			func(v *types.Value) { params.Path = v.ToString() },
+			func(v *types.Value) { params.Domain = v.ToString() },
+			func(v *types.Value) { params.Secure = v.ToBool() },
+			func(v *types.Value) { params.HTTPOnly = v.ToBool() },
+		}
+		for i, arg := range a[1:] {
+			if i < len(setters) && !arg.IsNull() {
+				setters[i](arg)
+			}
+		}
+		s.Cookie = params
+		return types.NewBool(true), nil
+	}
+
+	if len(a) > 1 {
+		return nil, vm.ThrowError("ArgumentCountError", "session_set_cookie_params(): Expects exactly 1 argument when argument #1 ($lifetime_or_options) is an array")
+	}
+	var unknown string
+	a[0].ToArray().Each(func(key, value *types.Value) bool {
+		value = value.Deref()
+		switch strings.ToLower(key.ToString()) {
+		case "lifetime":
+			params.Lifetime = value.ToInt()
+		case "path":
+			params.Path = value.ToString()
+		case "domain":
+			params.Domain = value.ToString()
+		case "secure":
+			params.Secure = value.ToBool()
+		case "httponly":
+			params.HTTPOnly = value.ToBool()
+		case "samesite":
+			params.SameSite = value.ToString()
+		default:
+			unknown = key.ToString()
+			return false
+		}
+		return true
+	})
+	if unknown != "" {
+		vm.warning("session_set_cookie_params(): Argument #1 ($lifetime_or_options) contains an unrecognized key \"%s\"", unknown)
+		return types.NewBool(false), nil
+	}
+	s.Cookie = params
+	return types.NewBool(true), nil
+}
+
+// registerSessionBuiltins registers the session functions, the session
+// handler interfaces and SessionHandler, which wraps the files handler
+func (vm *VM) registerSessionBuiltins() {
+	abstract := func(iface *types.InterfaceEntry, names ...string) *types.InterfaceEntry {
+		for _, name := range names {
+			iface.Methods[name] = &types.MethodDef{Name: name, Visibility: types.VisibilityPublic, IsAbstract: true}
+		}
+		vm.classes[iface.Name] = interfaceClass(iface)
+		return iface
+	}
+	handlerIface := abstract(types.NewInterfaceEntry("SessionHandlerInterface"), "open", "close", "read", "write", "destroy", "gc")
+	idIface := abstract(types.NewInterfaceEntry("SessionIdInterface"), "create_sid")
+	abstract(types.NewInterfaceEntry("SessionUpdateTimestampHandlerInterface"), "validateId", "updateTimestamp")
+
+	handler := types.NewClassEntry("SessionHandler")
+	handler.Interfaces = append(handler.Interfaces, handlerIface, idIface)
+	files := func(vm *VM) session.SaveHandler { return vm.sessionState().Default }
+	result := func(err error) (*types.Value, error) {
+		return types.NewBool(err == nil), nil
+	}
+	addNativeMethod(handler, "open", 2, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return result(files(vm).Open(args[0].Deref().ToString(), args[1].Deref().ToString()))
+	})
+	addNativeMethod(handler, "close", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return result(files(vm).Close())
+	})
+	addNativeMethod(handler, "read", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		data, err := files(vm).Read(args[0].Deref().ToString())
+		if err != nil {
+			return types.NewBool(false), nil
+		}
+		return types.NewString(data), nil
+	})
+	addNativeMethod(handler, "write", 2, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return result(files(vm).Write(args[0].Deref().ToString(), args[1].Deref().ToString()))
+	})
+	addNativeMethod(handler, "destroy", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return result(files(vm).Destroy(args[0].Deref().ToString()))
+	})
+	addNativeMethod(handler, "gc", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		n, err := files(vm).GC(args[0].Deref().ToInt())
+		if err != nil {
+			return types.NewBool(false), nil
+		}
+		return types.NewInt(n), nil
+	})
+	addNativeMethod(handler, "create_sid", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		id, err := session.RandomID()
+		if err != nil {
+			return nil, err
+		}
+		return types.NewString(id), nil
+	})
+	vm.classes[handler.Name] = handler
+	vm.classes["__PHP_Incomplete_Class"] = types.NewClassEntry("__PHP_Incomplete_Class")
+
+	for name, fn := range sessionFunctions {
+		vm.RegisterBuiltin(name, func(vm *VM, args []*types.Value) (*types.Value, error) {
+			if len(args) < fn.required {
+				return nil, fmt.Errorf("%s() expects at least %d argument(s), %d given", name, fn.required, len(args))
+			}
+			s := vm.sessionState()
+			s.Warning = func(message string) { vm.warning("%s(): %s", name, message) }
+			result, err := fn.call(vm, s, derefArgs(args))
+			return result, vm.sessionError(err)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/stdlib/filter"
+	"github.com/krizos/php-go/pkg/stdlib/session"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// sessionCaller calls builtins of a VM, failing the test on errors
+func sessionCaller(t *testing.T, vm *VM) func(name string, args ...*types.Value) *types.Value {
+	return func(name string, args ...*types.Value) *types.Value {
+		t.Helper()
+		result, err := vm.CallCallable(types.NewString(name), args)
+		if err != nil {
+			t.Fatalf("%s() failed: %v", name, err)
+		}
+		return result
+	}
+}
+
+func TestSessionBuiltins_FilesLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sess_abc123"), []byte(`user|s:3:"bob";`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := New()
+	call := sessionCaller(t, vm)
+	cookies := types.NewEmptyArray()
+	cookies.Set(types.NewString("PHPSESSID"), types.NewString("abc123"))
+	vm.SetRequestInput(filter.INPUT_COOKIE, cookies)
+	call("session_save_path", types.NewString(dir))
+
+	options := types.NewEmptyArray()
+	options.Set(types.NewString("gc_probability"), types.NewInt(0))
+	if result := call("session_start", types.NewArray(options)); !result.ToBool() {
+		t.Fatalf("session_start() failed: %s", vm.GetOutput())
+	}
+	if status := call("session_status"); status.ToInt() != session.PHP_SESSION_ACTIVE {
+		t.Errorf("session_status() = %v", status)
+	}
+	if id := call("session_id"); id.ToString() != "abc123" {
+		t.Errorf("session_id() = %v, want the cookie's ID", id)
+	}
+	vars, _ := vm.GetGlobal("_SESSION")
+	if user, _ := vars.ToArray().Get(types.NewString("user")); user.ToString() != "bob" {
+		t.Fatalf("$_SESSION = %v", vars)
+	}
+	if len(vm.ResponseHeaders()) != 0 {
+		t.Errorf("the cookie of a known session is not sent again: %q", vm.ResponseHeaders())
+	}
+
+	vars.ToArray().Set(types.NewString("visits"), types.NewInt(2))
+	if encoded := call("session_encode"); encoded.ToString() != `user|s:3:"bob";visits|i:2;` {
+		t.Errorf("session_encode() = %v", encoded)
+	}
+
+	// The session is written at the end of the request
+	if err := vm.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := os.ReadFile(filepath.Join(dir, "sess_abc123"))
+	if string(stored) != `user|s:3:"bob";visits|i:2;` {
+		t.Errorf("stored session = %q", stored)
+	}
+}
+
+func TestSessionBuiltins_NewSessionSendsCookie(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+	call("session_save_path", types.NewString(t.TempDir()))
+	call("session_set_cookie_params", types.NewInt(0), types.NewString("/app"), types.NewNull(), types.NewBool(true))
+
+	call("session_start")
+	id := call("session_id").ToString()
+	headers := vm.ResponseHeaders()
+	if len(headers) != 1 || headers[0] != "Set-Cookie: PHPSESSID="+id+"; path=/app; secure" {
+		t.Errorf("headers = %q", headers)
+	}
+
+	call("session_regenerate_id", types.NewBool(true))
+	if call("session_id").ToString() == id {
+		t.Errorf("session_regenerate_id() keeps the ID")
+	}
+
+	if result := call("session_id", types.NewString("other")); result.ToBool() {
+		t.Errorf("session_id() cannot change the ID of an active session")
+	}
+	if output := vm.GetOutput(); !strings.Contains(output, "session_id(): Session ID cannot be changed when a session is active") {
+		t.Errorf("expected a warning, got %q", output)
+	}
+}
+
+func TestSessionBuiltins_StartAfterOutput(t *testing.T) {
+	vm := New()
+	vm.writeOutput([]byte("hello"))
+	result, err := vm.CallCallable(types.NewString("session_start"), nil)
+	if err != nil || result.ToBool() {
+		t.Errorf("session_start() after output = %v, %v", result, err)
+	}
+	if output := vm.GetOutput(); !strings.Contains(output, "session_start(): Session cannot be started after headers have already been sent") {
+		t.Errorf("expected a warning, got %q", output)
+	}
+}
+
+func TestSessionBuiltins_UserSaveHandler(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+	stored := map[string]string{"u1": `n|i:1;`}
+	var calls []string
+	handler := func(name string, fn func(args []*types.Value) *types.Value) *types.Value {
+		vm.RegisterBuiltin("test_session_"+name, func(vm *VM, args []*types.Value) (*types.Value, error) {
+			calls = append(calls, name)
+			return fn(args), nil
+		})
+		return types.NewString("test_session_" + name)
+	}
+	ok := func(args []*types.Value) *types.Value { return types.NewBool(true) }
+
+	call("session_set_save_handler",
+		handler("open", ok),
+		handler("close", ok),
+		handler("read", func(args []*types.Value) *types.Value { return types.NewString(stored[args[0].ToString()]) }),
+		handler("write", func(args []*types.Value) *types.Value {
+			stored[args[0].ToString()] = args[1].ToString()
+			return types.NewBool(true)
+		}),
+		handler("destroy", ok),
+		handler("gc", func(args []*types.Value) *types.Value { return types.NewInt(0) }),
+		handler("create_sid", func(args []*types.Value) *types.Value { return types.NewString("u1") }),
+	)
+
+	call("session_start", types.NewArray(types.NewArrayFromMap(map[interface{}]*types.Value{"gc_probability": types.NewInt(0)})))
+	vars, _ := vm.GetGlobal("_SESSION")
+	if n, _ := vars.ToArray().Get(types.NewString("n")); n.ToInt() != 1 {
+		t.Fatalf("$_SESSION = %v (calls %v)", vars, calls)
+	}
+	vars.ToArray().Set(types.NewString("n"), types.NewInt(2))
+	call("session_write_close")
+	if stored["u1"] != "n|i:2;" || strings.Join(calls, ",") != "open,create_sid,read,write,close" {
+		t.Errorf("stored %q, calls %v", stored["u1"], calls)
+	}
+
+	_, err := vm.CallCallable(types.NewString("session_set_save_handler"), []*types.Value{types.NewInt(1)})
+	expectThrown(t, err, "TypeError", "session_set_save_handler(): Argument #1 ($open) must be of type SessionHandlerInterface, int given")
+}
+
+func TestSessionBuiltins_UnserializeUnknownClass(t *testing.T) {
+	vm := New()
+	obj, err := vm.serializer().Unserialize(`O:7:"Missing":1:{s:1:"a";i:1;}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if class := obj.ToObject().ClassName; class != "__PHP_Incomplete_Class" {
+		t.Errorf("class = %s", class)
+	}
+	if name := obj.ToObject().Properties["__PHP_Incomplete_Class_Name"]; name == nil || name.Value.ToString() != "Missing" {
+		t.Errorf("the original class name should be kept")
+	}
+}
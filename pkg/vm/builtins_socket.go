@@ -0,0 +1,204 @@
+package vm
+
+import (
+	"fmt"
+	"time"
+
+	stdfile "github.com/krizos/php-go/pkg/stdlib/file"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Socket Builtins
+// ============================================================================
+
+// registerSocketBuiltins registers the network stream functions whose
+// arguments are passed by reference or that report warnings
+func (vm *VM) registerSocketBuiltins() {
+	vm.RegisterBuiltin("fsockopen", builtinFsockopen("fsockopen"))
+	vm.RegisterBuiltin("pfsockopen", builtinFsockopen("pfsockopen"))
+	vm.RegisterBuiltin("stream_socket_client", builtinStreamSocketClient)
+	vm.RegisterBuiltin("stream_socket_server", builtinStreamSocketServer)
+	vm.RegisterBuiltin("stream_socket_accept", builtinStreamSocketAccept)
+	vm.RegisterBuiltin("stream_socket_recvfrom", builtinStreamSocketRecvfrom)
+	vm.RegisterBuiltin("stream_socket_enable_crypto", builtinStreamSocketEnableCrypto)
+	vm.RegisterBuiltin("stream_select", builtinStreamSelect)
+}
+
+// socketTimeout converts a timeout in seconds; null is the default socket
+// timeout and negative values wait indefinitely
+func socketTimeout(args []*types.Value, index int) time.Duration {
+	if index >= len(args) || args[index].Deref().IsNull() {
+		return types.DefaultSocketTimeout
+	}
+	seconds := args[index].Deref().ToFloat()
+	if seconds < 0 {
+		return -1
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// contextArg returns the stream context at args[index], if any
+func contextArg(args []*types.Value, index int) *stdfile.StreamContext {
+	if index >= len(args) {
+		return nil
+	}
+	ctx, _ := stdfile.ContextOf(args[index].Deref())
+	return ctx
+}
+
+// reportSocketError sets the $error_code and $error_message arguments
+func reportSocketError(args []*types.Value, codeArg int, err *stdfile.SocketError) {
+	code, message := 0, ""
+	if err != nil {
+		code, message = err.Code, err.Message
+	}
+	assignRefArg(args, codeArg, types.NewInt(int64(code)))
+	assignRefArg(args, codeArg+1, types.NewString(message))
+}
+
+// fsockopen(string $hostname, int $port = -1, int &$error_code = null, string &$error_message = null, ?float $timeout = null): resource|false
+func builtinFsockopen(name string) BuiltinFunction {
+	return func(vm *VM, args []*types.Value) (*types.Value, error) {
+		if len(args) < 1 {
+			return nil, fmt.Errorf("%s() expects at least 1 argument(s), 0 given", name)
+		}
+		hostname := args[0].Deref().ToString()
+		port := int64(-1)
+		if len(args) > 1 && !args[1].Deref().IsNull() {
+			port = args[1].Deref().ToInt()
+		}
+		stream, err := stdfile.SocketClient(hostname, port, socketTimeout(args, 4), nil)
+		reportSocketError(args, 2, err)
+		if err != nil {
+			if port >= 0 {
+				vm.warning("%s(): Unable to connect to %s:%d (%s)", name, hostname, port, err.Message)
+			} else {
+				vm.warning("%s(): Unable to connect to %s (%s)", name, hostname, err.Message)
+			}
+			return types.NewBool(false), nil
+		}
+		return stream, nil
+	}
+}
+
+// stream_socket_client(string $address, int &$error_code = null, string &$error_message = null, ?float $timeout = null, int $flags = STREAM_CLIENT_CONNECT, ?resource $context = null): resource|false
+func builtinStreamSocketClient(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("stream_socket_client() expects at least 1 argument(s), 0 given")
+	}
+	address := args[0].Deref().ToString()
+	stream, err := stdfile.SocketClient(address, -1, socketTimeout(args, 3), contextArg(args, 5))
+	reportSocketError(args, 1, err)
+	if err != nil {
+		vm.warning("stream_socket_client(): Unable to connect to %s (%s)", address, err.Message)
+		return types.NewBool(false), nil
+	}
+	return stream, nil
+}
+
+// stream_socket_server(string $address, int &$error_code = null, string &$error_message = null, int $flags = STREAM_SERVER_BIND | STREAM_SERVER_LISTEN, ?resource $context = null): resource|false
+func builtinStreamSocketServer(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("stream_socket_server() expects at least 1 argument(s), 0 given")
+	}
+	address := args[0].Deref().ToString()
+	flags := int64(stdfile.STREAM_SERVER_BIND | stdfile.STREAM_SERVER_LISTEN)
+	if len(args) > 3 {
+		flags = args[3].Deref().ToInt()
+	}
+	stream, err := stdfile.SocketServer(address, flags, contextArg(args, 4))
+	reportSocketError(args, 1, err)
+	if err != nil {
+		vm.warning("stream_socket_server(): Unable to connect to %s (%s)", address, err.Message)
+		return types.NewBool(false), nil
+	}
+	return stream, nil
+}
+
+// stream_socket_accept(resource $socket, ?float $timeout = null, string &$peer_name = null): resource|false
+func builtinStreamSocketAccept(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("stream_socket_accept() expects at least 1 argument(s), 0 given")
+	}
+	stream, peer, err := stdfile.SocketAccept(args[0].Deref(), socketTimeout(args, 1))
+	if err != nil {
+		vm.warning("stream_socket_accept(): %s", err)
+		return types.NewBool(false), nil
+	}
+	assignRefArg(args, 2, types.NewString(peer))
+	return stream, nil
+}
+
+// stream_socket_recvfrom(resource $socket, int $length, int $flags = 0, ?string &$address = null): string|false
+func builtinStreamSocketRecvfrom(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("stream_socket_recvfrom() expects at least 2 argument(s), %d given", len(args))
+	}
+	flags := types.NewInt(0)
+	if len(args) > 2 {
+		flags = args[2].Deref()
+	}
+	data, address := stdfile.SocketRecvFrom(args[0].Deref(), args[1].Deref(), flags)
+	assignRefArg(args, 3, types.NewString(address))
+	return data, nil
+}
+
+// stream_socket_enable_crypto(resource $stream, bool $enable, ?int $crypto_method = null, ?resource $session_stream = null): int|bool
+func builtinStreamSocketEnableCrypto(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("stream_socket_enable_crypto() expects at least 2 argument(s), %d given", len(args))
+	}
+	args = derefArgs(args)
+	var method *types.Value
+	if len(args) > 2 {
+		method = args[2]
+	}
+	result, err := stdfile.EnableCrypto(args[0], args[1].ToBool(), method)
+	if err != nil {
+		vm.warning("stream_socket_enable_crypto(): %s", err)
+	}
+	return result, nil
+}
+
+// stream_select(?array &$read, ?array &$write, ?array &$except, ?int $seconds, ?int $microseconds = null): int|false
+// The arrays are replaced by the streams that are ready, keeping their
+// keys; no stream has exceptional conditions.
+func builtinStreamSelect(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 4 {
+		return nil, fmt.Errorf("stream_select() expects at least 4 argument(s), %d given", len(args))
+	}
+	streams := func(i int) *types.Array {
+		if value := args[i].Deref(); value.Type() == types.TypeArray {
+			return value.ToArray()
+		}
+		return nil
+	}
+	read, write, except := streams(0), streams(1), streams(2)
+	if read == nil && write == nil && except == nil {
+		return nil, vm.ThrowError("ValueError", "stream_select(): No stream arrays were passed")
+	}
+
+	timeout := time.Duration(-1)
+	if seconds := args[3].Deref(); !seconds.IsNull() {
+		if seconds.ToInt() < 0 {
+			return nil, vm.ThrowError("ValueError", "stream_select(): Argument #4 ($seconds) must be greater than or equal to 0")
+		}
+		timeout = time.Duration(seconds.ToInt()) * time.Second
+		if len(args) > 4 && !args[4].Deref().IsNull() {
+			timeout += time.Duration(args[4].Deref().ToInt()) * time.Microsecond
+		}
+	}
+
+	readyRead, readyWrite := stdfile.Select(read, write, timeout)
+	if read != nil {
+		assignRefArg(args, 0, types.NewArray(readyRead))
+	}
+	if write != nil {
+		assignRefArg(args, 1, types.NewArray(readyWrite))
+	}
+	if except != nil {
+		assignRefArg(args, 2, types.NewArray(types.NewEmptyArray()))
+	}
+	return types.NewInt(int64(readyRead.Len() + readyWrite.Len())), nil
+}
@@ -0,0 +1,66 @@
+package vm
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestSocketBuiltins_EchoServer(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+	server := call("stream_socket_server", types.NewString("tcp://127.0.0.1:0"))
+	if !server.IsResource() {
+		t.Fatalf("stream_socket_server() = %v (%s)", server, vm.GetOutput())
+	}
+	address := call("stream_socket_get_name", server, types.NewBool(false)).ToString()
+
+	code, message := types.NewReference(types.NewNull()), types.NewReference(types.NewNull())
+	client := call("stream_socket_client", types.NewString("tcp://"+address), code, message, types.NewFloat(1))
+	if !client.IsResource() || code.Deref().ToInt() != 0 || message.Deref().ToString() != "" {
+		t.Fatalf("stream_socket_client() = %v, %v %v", client, code, message)
+	}
+
+	read := types.NewReference(types.NewArray(types.NewArrayFromMap(map[interface{}]*types.Value{0: server})))
+	if n := call("stream_select", read, types.NewNull(), types.NewNull(), types.NewInt(1)); n.ToInt() != 1 {
+		t.Fatalf("stream_select() = %v", n)
+	}
+	peer := types.NewReference(types.NewNull())
+	conn := call("stream_socket_accept", server, types.NewInt(1), peer)
+	if peer.Deref().ToString() != call("stream_socket_get_name", client, types.NewBool(false)).ToString() {
+		t.Errorf("peer name = %v", peer)
+	}
+
+	call("fwrite", client, types.NewString("ping\n"))
+	if line := call("fgets", conn); line.ToString() != "ping\n" {
+		t.Errorf("fgets() = %v", line)
+	}
+	call("fclose", client)
+	call("fclose", conn)
+	call("fclose", server)
+}
+
+func TestSocketBuiltins_Errors(t *testing.T) {
+	probe, _ := net.Listen("tcp", "127.0.0.1:0")
+	host, port, _ := net.SplitHostPort(probe.Addr().String())
+	probe.Close()
+
+	vm := New()
+	call := sessionCaller(t, vm)
+	code, message := types.NewReference(types.NewNull()), types.NewReference(types.NewNull())
+	if result := call("fsockopen", types.NewString(host), types.NewString(port), code, message); result.ToBool() {
+		t.Fatalf("fsockopen() to a closed port = %v", result)
+	}
+	if code.Deref().ToInt() == 0 || message.Deref().ToString() != "Connection refused" {
+		t.Errorf("error = %v %v", code, message)
+	}
+	want := "fsockopen(): Unable to connect to " + host + ":" + port + " (Connection refused)"
+	if !strings.Contains(vm.GetOutput(), want) {
+		t.Errorf("expected %q, got %q", want, vm.GetOutput())
+	}
+
+	_, err := vm.CallCallable(types.NewString("stream_select"), []*types.Value{types.NewNull(), types.NewNull(), types.NewNull(), types.NewInt(0)})
+	expectThrown(t, err, "ValueError", "stream_select(): No stream arrays were passed")
+}
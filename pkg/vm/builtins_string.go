@@ -0,0 +1,252 @@
+package vm
+
+import (
+	"fmt"
+
+	stdfile "github.com/krizos/php-go/pkg/stdlib/file"
+	stdstring "github.com/krizos/php-go/pkg/stdlib/string"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Formatted String Builtins
+// ============================================================================
+
+// registerStringBuiltins registers the printf and scanf families.
+// printf() and vprintf() write to the script output, fprintf() and
+// vfprintf() to a stream; sscanf() assigns its by-reference arguments.
+func (vm *VM) registerStringBuiltins() {
+	vm.RegisterBuiltin("sprintf", builtinSprintf)
+	vm.RegisterBuiltin("vsprintf", builtinVsprintf)
+	vm.RegisterBuiltin("printf", builtinPrintf)
+	vm.RegisterBuiltin("vprintf", builtinVprintf)
+	vm.RegisterBuiltin("fprintf", builtinFprintf)
+	vm.RegisterBuiltin("vfprintf", builtinVfprintf)
+	vm.RegisterBuiltin("sscanf", builtinSscanf)
+	vm.RegisterBuiltin("similar_text", builtinSimilarText)
+
+	for name, fn := range stringFunctions {
+		vm.RegisterBuiltin(name, fn.builtin(name))
+	}
+}
+
+// format formats args for a printf-family function. extra is the number
+// of parameters before the values (the format, a stream), for the
+// ArgumentCountError message; vformat functions pass -1 as their values
+// come from an array.
+func (vm *VM) format(function string, format *types.Value, args []*types.Value, extra int) (string, error) {
+	result, err := stdstring.Format(format.Deref().ToString(), args)
+	switch e := err.(type) {
+	case nil:
+		return result, nil
+	case *stdstring.MissingArgumentsError:
+		if extra < 0 {
+			return "", vm.ThrowError("ValueError", "%s(): The arguments array must contain %d items, %d given", function, e.Required, e.Given)
+		}
+		e.Extra = extra
+		return "", vm.ThrowError("ArgumentCountError", "%s", e.Error())
+	case *stdstring.FormatError:
+		return "", vm.ThrowError(e.Class, "%s", e.Message)
+	default:
+		return "", err
+	}
+}
+
+// vformatArgs returns the values of the array argument of a vprintf-family
+// function
+func (vm *VM) vformatArgs(function string, position int, args []*types.Value) ([]*types.Value, error) {
+	values := args[position].Deref()
+	if !values.IsArray() {
+		return nil, vm.ThrowError("TypeError", "%s(): Argument #%d ($values) must be of type array, %s given", function, position+1, values.TypeName())
+	}
+	return stdstring.FormatArgs(values), nil
+}
+
+// sprintf(string $format, mixed ...$values): string
+func builtinSprintf(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("sprintf() expects at least 1 argument, 0 given")
+	}
+	result, err := vm.format("sprintf", args[0], args[1:], 1)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewString(result), nil
+}
+
+// vsprintf(string $format, array $values): string
+func builtinVsprintf(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("vsprintf() expects exactly 2 arguments, %d given", len(args))
+	}
+	values, err := vm.vformatArgs("vsprintf", 1, args)
+	if err != nil {
+		return nil, err
+	}
+	result, err := vm.format("vsprintf", args[0], values, -1)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewString(result), nil
+}
+
+// printf(string $format, mixed ...$values): int
+func builtinPrintf(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("printf() expects at least 1 argument, 0 given")
+	}
+	result, err := vm.format("printf", args[0], args[1:], 1)
+	if err != nil {
+		return nil, err
+	}
+	vm.writeOutput([]byte(result))
+	return types.NewInt(int64(len(result))), nil
+}
+
+// vprintf(string $format, array $values): int
+func builtinVprintf(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("vprintf() expects exactly 2 arguments, %d given", len(args))
+	}
+	values, err := vm.vformatArgs("vprintf", 1, args)
+	if err != nil {
+		return nil, err
+	}
+	result, err := vm.format("vprintf", args[0], values, -1)
+	if err != nil {
+		return nil, err
+	}
+	vm.writeOutput([]byte(result))
+	return types.NewInt(int64(len(result))), nil
+}
+
+// fprintf(resource $stream, string $format, mixed ...$values): int
+func builtinFprintf(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("fprintf() expects at least 2 arguments, %d given", len(args))
+	}
+	result, err := vm.format("fprintf", args[1], args[2:], 2)
+	if err != nil {
+		return nil, err
+	}
+	return stdfile.Fwrite(args[0].Deref(), types.NewString(result)), nil
+}
+
+// vfprintf(resource $stream, string $format, array $values): int
+func builtinVfprintf(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("vfprintf() expects exactly 3 arguments, %d given", len(args))
+	}
+	values, err := vm.vformatArgs("vfprintf", 2, args)
+	if err != nil {
+		return nil, err
+	}
+	result, err := vm.format("vfprintf", args[1], values, -1)
+	if err != nil {
+		return nil, err
+	}
+	return stdfile.Fwrite(args[0].Deref(), types.NewString(result)), nil
+}
+
+// sscanf(string $string, string $format, mixed &...$vars): array|int|null
+// Without $vars the values are returned as an array; otherwise they are
+// assigned to $vars and the number of assigned values is returned.
+func builtinSscanf(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("sscanf() expects at least 2 arguments, %d given", len(args))
+	}
+	values, count, err := stdstring.Scan(args[0].Deref().ToString(), args[1].Deref().ToString())
+	if e, ok := err.(*stdstring.FormatError); ok {
+		return nil, vm.ThrowError(e.Class, "%s", e.Message)
+	}
+
+	if len(args) == 2 {
+		if count == -1 {
+			return types.NewNull(), nil
+		}
+		return types.NewArray(types.NewArrayFromSlice(values)), nil
+	}
+
+	if len(values) != len(args)-2 {
+		return nil, vm.ThrowError("ValueError", "Different numbers of variable names and field specifiers")
+	}
+	for i, value := range values {
+		if !value.IsNull() {
+			assignRefArg(args, i+2, value)
+		}
+	}
+	return types.NewInt(int64(count)), nil
+}
+
+// ============================================================================
+// String Builtins
+// ============================================================================
+
+// stringFunction adapts a pkg/stdlib/string function to a builtin, like
+// arrayFunction
+type stringFunction struct {
+	required int
+	call     func(vm *VM, args []*types.Value) (*types.Value, error)
+}
+
+// stringFunctions maps string functions to their pkg/stdlib/string
+// implementations
+var stringFunctions = map[string]stringFunction{
+	"strlen": {1, pure(func(a []*types.Value) *types.Value { return stdstring.Strlen(a[0]) })},
+	"substr": {2, pure(func(a []*types.Value) *types.Value { return stdstring.Substr(a[0], a[1], a[2:]...) })},
+
+	"str_contains":    {2, pure(func(a []*types.Value) *types.Value { return stdstring.StrContains(a[0], a[1]) })},
+	"str_starts_with": {2, pure(func(a []*types.Value) *types.Value { return stdstring.StrStartsWith(a[0], a[1]) })},
+	"str_ends_with":   {2, pure(func(a []*types.Value) *types.Value { return stdstring.StrEndsWith(a[0], a[1]) })},
+	"substr_count": {2, func(vm *VM, a []*types.Value) (*types.Value, error) {
+		return stdstring.SubstrCount(a[0], a[1], a[2:]...)
+	}},
+	"strtr": {2, func(vm *VM, a []*types.Value) (*types.Value, error) {
+		return stdstring.Strtr(a[0], a[1:]...)
+	}},
+	"str_word_count": {1, func(vm *VM, a []*types.Value) (*types.Value, error) {
+		return stdstring.StrWordCount(a[0], a[1:]...)
+	}},
+
+	"levenshtein": {2, pure(func(a []*types.Value) *types.Value { return stdstring.Levenshtein(a[0], a[1], a[2:]...) })},
+	"soundex":     {1, pure(func(a []*types.Value) *types.Value { return stdstring.Soundex(a[0]) })},
+	"metaphone": {1, func(vm *VM, a []*types.Value) (*types.Value, error) {
+		return stdstring.Metaphone(a[0], a[1:]...)
+	}},
+
+	"quoted_printable_encode": {1, pure(func(a []*types.Value) *types.Value { return stdstring.QuotedPrintableEncode(a[0]) })},
+	"quoted_printable_decode": {1, pure(func(a []*types.Value) *types.Value { return stdstring.QuotedPrintableDecode(a[0]) })},
+	"convert_uuencode":        {1, pure(func(a []*types.Value) *types.Value { return stdstring.ConvertUuencode(a[0]) })},
+	"convert_uudecode": {1, func(vm *VM, a []*types.Value) (*types.Value, error) {
+		result, ok := stdstring.ConvertUudecode(a[0])
+		if !ok {
+			vm.warning("convert_uudecode(): Argument #1 ($data) is not a valid uuencoded string")
+		}
+		return result, nil
+	}},
+}
+
+// builtin wraps the function with an argument count check
+func (fn stringFunction) builtin(name string) BuiltinFunction {
+	return func(vm *VM, args []*types.Value) (*types.Value, error) {
+		if len(args) < fn.required {
+			return nil, fmt.Errorf("%s() expects at least %d argument(s), %d given", name, fn.required, len(args))
+		}
+		result, err := fn.call(vm, derefArgs(args))
+		if e, ok := err.(*stdstring.Error); ok {
+			return nil, vm.ThrowError(e.Class, "%s", e.Message)
+		}
+		return result, err
+	}
+}
+
+// similar_text(string $string1, string $string2, float &$percent = null): int
+// The similarity percentage is assigned to $percent.
+func builtinSimilarText(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("similar_text() expects at least 2 arguments, %d given", len(args))
+	}
+	similar, percent := stdstring.SimilarText(args[0].Deref(), args[1].Deref())
+	assignRefArg(args, 2, percent)
+	return similar, nil
+}
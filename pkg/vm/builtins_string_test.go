@@ -0,0 +1,193 @@
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestStringBuiltins_Printf(t *testing.T) {
+	vm := New()
+
+	call := func(name string, args ...*types.Value) *types.Value {
+		t.Helper()
+		result, err := vm.CallCallable(types.NewString(name), args)
+		if err != nil {
+			t.Fatalf("%s() failed: %v", name, err)
+		}
+		return result
+	}
+	values := types.NewArray(types.NewArrayFromSlice([]*types.Value{types.NewString("b"), types.NewInt(2)}))
+
+	if s := call("sprintf", types.NewString("%'.8.2f|%-4s|"), types.NewFloat(3.14159), types.NewString("ab")); s.ToString() != "....3.14|ab  |" {
+		t.Errorf("sprintf() = %q", s.ToString())
+	}
+	if s := call("vsprintf", types.NewString("%2$d%1$s"), values); s.ToString() != "2b" {
+		t.Errorf("vsprintf() = %q", s.ToString())
+	}
+	if n := call("printf", types.NewString("[%03d]"), types.NewInt(7)); n.ToInt() != 5 {
+		t.Errorf("printf() = %d, want 5", n.ToInt())
+	}
+	if n := call("vprintf", types.NewString("%s%d"), values); n.ToInt() != 2 {
+		t.Errorf("vprintf() = %d, want 2", n.ToInt())
+	}
+	if output := vm.GetOutput(); output != "[007]b2" {
+		t.Errorf("Expected output '[007]b2', got %q", output)
+	}
+}
+
+func TestStringBuiltins_Fprintf(t *testing.T) {
+	vm := New()
+	path := filepath.Join(t.TempDir(), "out.txt")
+
+	handle, err := vm.CallCallable(types.NewString("fopen"), []*types.Value{types.NewString(path), types.NewString("w")})
+	if err != nil {
+		t.Fatalf("fopen() failed: %v", err)
+	}
+	n, err := vm.CallCallable(types.NewString("fprintf"), []*types.Value{handle, types.NewString("%s=%x\n"), types.NewString("k"), types.NewInt(255)})
+	if err != nil || n.ToInt() != 5 {
+		t.Fatalf("fprintf() = %v, %v; want 5", n, err)
+	}
+	values := types.NewArray(types.NewArrayFromSlice([]*types.Value{types.NewInt(1)}))
+	if _, err := vm.CallCallable(types.NewString("vfprintf"), []*types.Value{handle, types.NewString("%b"), values}); err != nil {
+		t.Fatalf("vfprintf() failed: %v", err)
+	}
+	vm.CallCallable(types.NewString("fclose"), []*types.Value{handle})
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "k=ff\n1" {
+		t.Errorf("Expected file contents 'k=ff\\n1', got %q", data)
+	}
+}
+
+func TestStringBuiltins_FormatErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []*types.Value
+		class   string
+		message string
+	}{
+		{"sprintf", []*types.Value{types.NewString("%d %d"), types.NewInt(1)}, "ArgumentCountError", "3 arguments are required, 2 given"},
+		{"fprintf", []*types.Value{types.NewNull(), types.NewString("%s")}, "ArgumentCountError", "3 arguments are required, 2 given"},
+		{"vsprintf", []*types.Value{types.NewString("%s %s"), types.NewArray(types.NewArrayFromSlice(nil))}, "ValueError", "vsprintf(): The arguments array must contain 2 items, 0 given"},
+		{"vsprintf", []*types.Value{types.NewString("%s"), types.NewString("x")}, "TypeError", "vsprintf(): Argument #2 ($values) must be of type array, string given"},
+		{"sprintf", []*types.Value{types.NewString("%q"), types.NewInt(1)}, "ValueError", "Unknown format specifier \"q\""},
+		{"sscanf", []*types.Value{types.NewString("1"), types.NewString("%d"), types.NewReference(types.NewNull()), types.NewReference(types.NewNull())}, "ValueError", "Different numbers of variable names and field specifiers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := New()
+			_, err := vm.CallCallable(types.NewString(tt.name), tt.args)
+			throwable, ok := err.(*ThrowableError)
+			if !ok || throwable.Object.ClassEntry.Name != tt.class {
+				t.Fatalf("Expected a %s, got %v", tt.class, err)
+			}
+			if msg := throwableProperty(throwable.Object, "message").ToString(); msg != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, msg)
+			}
+		})
+	}
+}
+
+func TestStringBuiltins_Sscanf(t *testing.T) {
+	vm := New()
+
+	result, err := vm.CallCallable(types.NewString("sscanf"), []*types.Value{types.NewString("age: 30"), types.NewString("%s %d")})
+	if err != nil {
+		t.Fatalf("sscanf() failed: %v", err)
+	}
+	if !result.IsArray() || result.ToArray().Len() != 2 {
+		t.Fatalf("Expected an array of 2 values, got %v", result)
+	}
+
+	name, age := types.NewReference(types.NewNull()), types.NewReference(types.NewString("unchanged"))
+	count, err := vm.CallCallable(types.NewString("sscanf"), []*types.Value{types.NewString("bob"), types.NewString("%s %d"), name, age})
+	if err != nil {
+		t.Fatalf("sscanf() failed: %v", err)
+	}
+	if count.ToInt() != 1 {
+		t.Errorf("Expected 1 assigned value, got %d", count.ToInt())
+	}
+	if name.Deref().ToString() != "bob" || age.Deref().ToString() != "unchanged" {
+		t.Errorf("Expected only $name to be assigned, got %v and %v", name.Deref(), age.Deref())
+	}
+
+	count, _ = vm.CallCallable(types.NewString("sscanf"), []*types.Value{types.NewString(""), types.NewString("%d"), name})
+	if count.ToInt() != -1 {
+		t.Errorf("Expected -1 for input that ended before the first conversion, got %d", count.ToInt())
+	}
+}
+
+func TestStringBuiltins_SimilarTextAndErrors(t *testing.T) {
+	vm := New()
+
+	percent := types.NewReference(types.NewNull())
+	similar, err := vm.CallCallable(types.NewString("similar_text"), []*types.Value{types.NewString("World"), types.NewString("Word"), percent})
+	if err != nil {
+		t.Fatalf("similar_text() failed: %v", err)
+	}
+	if similar.ToInt() != 4 || !percent.Deref().IsFloat() {
+		t.Errorf("Expected 4 similar bytes and $percent assigned, got %v and %v", similar, percent.Deref())
+	}
+
+	_, err = vm.CallCallable(types.NewString("substr_count"), []*types.Value{types.NewString("abc"), types.NewString("")})
+	expectThrown(t, err, "ValueError", "substr_count(): Argument #2 ($needle) cannot be empty")
+
+	result, err := vm.CallCallable(types.NewString("convert_uudecode"), []*types.Value{types.NewString("M0123")})
+	if err != nil || result.ToBool() {
+		t.Errorf("Expected false for invalid data, got %v (%v)", result, err)
+	}
+	if !strings.Contains(vm.GetOutput(), "is not a valid uuencoded string") {
+		t.Errorf("Expected a warning for invalid data, got %q", vm.GetOutput())
+	}
+}
+
+func TestStringBuiltins_BinarySafe(t *testing.T) {
+	vm := New()
+	vm.SetScriptPath("test.php")
+	str := types.NewString("a\x00\xffb")
+
+	length, err := vm.CallCallable(types.NewString("strlen"), []*types.Value{str})
+	if err != nil || length.ToInt() != 4 {
+		t.Errorf("strlen() = %v (%v), want 4", length, err)
+	}
+	sub, err := vm.CallCallable(types.NewString("substr"), []*types.Value{str, types.NewInt(1), types.NewInt(2)})
+	if err != nil || sub.ToString() != "\x00\xff" {
+		t.Errorf("substr() = %q (%v)", sub.ToString(), err)
+	}
+
+	// Concatenated and echoed, the bytes reach the output unchanged
+	frame := NewFrame(&CompiledFunction{Name: "f", NumLocals: 3})
+	frame.setLocal(0, str)
+	frame.setLocal(1, sub)
+	if err := vm.dispatchOpcode(frame, Instruction{Opcode: OpConcat, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpCV, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 2}}); err != nil {
+		t.Fatalf("CONCAT failed: %v", err)
+	}
+	if err := vm.dispatchOpcode(frame, Instruction{Opcode: OpEcho, Op1: Operand{Type: OpTmpVar, Value: 2}}); err != nil {
+		t.Fatalf("ECHO failed: %v", err)
+	}
+	if output := vm.GetOutput(); output != "a\x00\xffb\x00\xff" {
+		t.Errorf("Expected the raw bytes, got %q", output)
+	}
+}
+
+func TestCtypeBuiltins(t *testing.T) {
+	vm := New()
+
+	result, err := vm.CallCallable(types.NewString("ctype_digit"), []*types.Value{types.NewString("0123")})
+	if err != nil || !result.ToBool() {
+		t.Errorf("Expected ctype_digit(\"0123\") to be true, got %v (%v)", result, err)
+	}
+
+	result, err = vm.CallCallable(types.NewString("ctype_digit"), []*types.Value{types.NewInt(53)})
+	if err != nil || !result.ToBool() {
+		t.Errorf("Expected ctype_digit(53) to check the character \"5\", got %v (%v)", result, err)
+	}
+	if !strings.Contains(vm.GetOutput(), "ctype_digit(): Argument of type int will be interpreted as string in the future") {
+		t.Errorf("Expected a deprecation for an int argument, got %q", vm.GetOutput())
+	}
+}
@@ -0,0 +1,107 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/stdlib/vartype"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Variable Type Builtins
+// ============================================================================
+
+// typeFunctions maps the type checking functions, which take a single
+// value and cannot fail, to their pkg/stdlib/vartype implementations
+var typeFunctions = map[string]func(val *types.Value) *types.Value{
+	"is_null":        vartype.IsNull,
+	"is_bool":        vartype.IsBool,
+	"is_int":         vartype.IsInt,
+	"is_integer":     vartype.IsInteger,
+	"is_long":        vartype.IsLong,
+	"is_float":       vartype.IsFloat,
+	"is_double":      vartype.IsDouble,
+	"is_string":      vartype.IsString,
+	"is_array":       vartype.IsArray,
+	"is_object":      vartype.IsObject,
+	"is_numeric":     vartype.IsNumeric,
+	"is_scalar":      vartype.IsScalar,
+	"is_iterable":    vartype.IsIterable,
+	"is_countable":   vartype.IsCountable,
+	"gettype":        vartype.GetType,
+	"get_debug_type": vartype.GetDebugType,
+	"boolval":        vartype.Boolval,
+}
+
+// registerVartypeBuiltins registers the type checking and conversion
+// functions. The compiler turns the calls of the most common ones on a
+// variable into TYPE_CHECK and CAST instructions.
+func (vm *VM) registerVartypeBuiltins() {
+	for name, fn := range typeFunctions {
+		vm.RegisterBuiltin(name, func(vm *VM, args []*types.Value) (*types.Value, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("%s() expects exactly 1 argument, %d given", name, len(args))
+			}
+			return fn(args[0].Deref()), nil
+		})
+	}
+
+	vm.RegisterBuiltin("intval", builtinIntval)
+	vm.RegisterBuiltin("floatval", builtinFloatval)
+	vm.RegisterBuiltin("doubleval", builtinFloatval)
+	vm.RegisterBuiltin("strval", builtinStrval)
+	vm.RegisterBuiltin("settype", builtinSettype)
+}
+
+// converter returns the conversions with the VM's hooks: warnings are
+// reported and objects converted to string with __toString()
+func (vm *VM) converter() *vartype.Converter {
+	return &vartype.Converter{
+		Warning:  func(message string) { vm.warning("%s", message) },
+		Stringer: vm.stringable,
+	}
+}
+
+// conversionError throws the errors of the vartype package as the
+// exception class they name
+func (vm *VM) conversionError(err error) error {
+	if e, ok := err.(*vartype.Error); ok {
+		return vm.ThrowError(e.Class, "%s", e.Message)
+	}
+	return err
+}
+
+// intval(mixed $value, int $base = 10): int
+func builtinIntval(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("intval() expects 1 or 2 arguments, %d given", len(args))
+	}
+	args = derefArgs(args)
+	return vm.converter().Intval(args[0], args[1:]...), nil
+}
+
+// floatval(mixed $value): float
+func builtinFloatval(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("floatval() expects exactly 1 argument, %d given", len(args))
+	}
+	return vm.converter().Floatval(args[0].Deref()), nil
+}
+
+// strval(mixed $value): string
+func builtinStrval(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("strval() expects exactly 1 argument, %d given", len(args))
+	}
+	result, err := vm.converter().Strval(args[0].Deref())
+	return result, vm.conversionError(err)
+}
+
+// settype(mixed &$var, string $type): bool
+func builtinSettype(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("settype() expects exactly 2 arguments, %d given", len(args))
+	}
+	result, err := vm.converter().Settype(args[0], args[1].Deref())
+	return result, vm.conversionError(err)
+}
@@ -0,0 +1,119 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/stdlib/vartype"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// runTypeOp executes a TYPE_CHECK or CAST of a value held in a compiled
+// variable and returns its result
+func runTypeOp(t *testing.T, vm *VM, opcode Opcode, extended uint32, value *types.Value) (*types.Value, error) {
+	t.Helper()
+	frame := NewFrame(&CompiledFunction{Name: "f", NumLocals: 2})
+	frame.setLocal(0, value)
+	instr := Instruction{Opcode: opcode, ExtendedValue: extended, Op1: Operand{Type: OpCV, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 1}}
+	if err := vm.dispatchOpcode(frame, instr); err != nil {
+		return nil, err
+	}
+	return vm.getOperandValue(frame, instr.Result)
+}
+
+func TestTypeCheck(t *testing.T) {
+	vm := New()
+	res := types.NewResourceHandle("stream", nil)
+
+	tests := []struct {
+		mask     uint32
+		value    *types.Value
+		expected bool
+	}{
+		{TypeCheckInt, types.NewInt(1), true},
+		{TypeCheckInt, types.NewString("1"), false},
+		{TypeCheckBool, types.NewBool(false), true},
+		{TypeCheckNull, types.NewNull(), true},
+		{TypeCheckScalar, types.NewFloat(1.5), true},
+		{TypeCheckScalar, types.NewArray(types.NewEmptyArray()), false},
+		{TypeCheckInt, types.NewReference(types.NewInt(1)), true},
+		{TypeCheckResource, types.NewResource(res), true},
+	}
+	for _, tt := range tests {
+		result, err := runTypeOp(t, vm, OpTypeCheck, tt.mask, tt.value)
+		if err != nil || result.ToBool() != tt.expected {
+			t.Errorf("TYPE_CHECK %b of %v = %v (%v), want %v", tt.mask, tt.value, result, err, tt.expected)
+		}
+	}
+
+	res.Close()
+	if result, _ := runTypeOp(t, vm, OpTypeCheck, TypeCheckResource, types.NewResource(res)); result.ToBool() {
+		t.Error("Expected closed resources not to be resources")
+	}
+}
+
+func TestCast(t *testing.T) {
+	vm := New()
+	vm.SetScriptPath("test.php")
+
+	result, err := runTypeOp(t, vm, OpCast, uint32(vartype.CastInt), types.NewString("1e3"))
+	if err != nil || !result.IsInt() || result.ToInt() != 1000 {
+		t.Errorf("(int) \"1e3\" = %v (%v)", result, err)
+	}
+	result, _ = runTypeOp(t, vm, OpCast, uint32(vartype.CastString), types.NewArray(types.NewEmptyArray()))
+	if result.ToString() != "Array" || !strings.Contains(vm.GetOutput(), "Warning: Array to string conversion") {
+		t.Errorf("(string) [] = %v with output %q", result, vm.GetOutput())
+	}
+
+	// Objects are converted with __toString()
+	class := types.NewClassEntry("Name")
+	addNativeMethod(class, "__toString", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return types.NewString("name"), nil
+	})
+	vm.classes["Name"] = class
+	result, err = runTypeOp(t, vm, OpCast, uint32(vartype.CastString), types.NewObject(types.NewObjectFromClass(class)))
+	if err != nil || result.ToString() != "name" {
+		t.Errorf("(string) Name = %v (%v)", result, err)
+	}
+	_, err = runTypeOp(t, vm, OpCast, uint32(vartype.CastString), types.NewObject(types.NewObjectInstance("Plain")))
+	expectThrown(t, err, "Error", "Object of class Plain could not be converted to string")
+}
+
+func TestTypeOpUndefinedVariable(t *testing.T) {
+	vm := New()
+	vm.SetScriptPath("test.php")
+
+	// is_int($x); intval($x); with $x undefined warn like reading $x
+	err := vm.ExecuteScript(&Script{
+		Instructions: Instructions{
+			{Opcode: OpTypeCheck, ExtendedValue: TypeCheckNull, Op1: Operand{Type: OpCV, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 10}},
+			{Opcode: OpCast, ExtendedValue: uint32(vartype.CastInt), Op1: Operand{Type: OpCV, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 11}},
+		},
+		Variables: []string{"x"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := vm.GetOutput(); strings.Count(got, "Warning: Undefined variable $x") != 2 {
+		t.Errorf("Expected two warnings, got %q", got)
+	}
+}
+
+func TestBuiltin_Settype(t *testing.T) {
+	vm := New()
+	variable := types.NewReference(types.NewString("3.5"))
+
+	result, err := vm.builtins["settype"](vm, []*types.Value{variable, types.NewString("float")})
+	if err != nil || !result.ToBool() || !variable.Deref().IsFloat() || variable.Deref().ToFloat() != 3.5 {
+		t.Errorf("Expected float(3.5), got %v (%v)", variable.Deref(), err)
+	}
+	_, err = vm.builtins["settype"](vm, []*types.Value{variable, types.NewString("number")})
+	expectThrown(t, err, "ValueError", "settype(): Argument #2 ($type) must be a valid type")
+
+	if got, _ := vm.builtins["intval"](vm, []*types.Value{types.NewString("ff"), types.NewInt(16)}); got.ToInt() != 255 {
+		t.Errorf("intval(\"ff\", 16) = %v", got)
+	}
+	if got, _ := vm.builtins["is_numeric"](vm, []*types.Value{types.NewString("1e3 ")}); !got.ToBool() {
+		t.Errorf("is_numeric(\"1e3 \") = %v", got)
+	}
+}
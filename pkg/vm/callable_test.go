@@ -0,0 +1,349 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Callable Test Helpers
+// ============================================================================
+
+// doubleFunction returns a function computing $x + $x
+func doubleFunction(name string) *CompiledFunction {
+	return &CompiledFunction{
+		Name: name,
+		Instructions: Instructions{
+			{Opcode: OpAdd, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpCV, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 0}},
+			{Opcode: OpReturn, Op1: Operand{Type: OpTmpVar, Value: 0}},
+		},
+		NumLocals: 10,
+		NumParams: 1,
+	}
+}
+
+// doubleMethod returns a method definition computing $x + $x
+func doubleMethod(name string, static bool) *types.MethodDef {
+	fn := doubleFunction(name)
+	instrs := make([]interface{}, len(fn.Instructions))
+	for i, instr := range fn.Instructions {
+		instrs[i] = instr
+	}
+	return &types.MethodDef{
+		Name:         name,
+		Visibility:   types.VisibilityPublic,
+		IsStatic:     static,
+		Instructions: instrs,
+		NumLocals:    10,
+		NumParams:    1,
+	}
+}
+
+func newCallableTestVM() *VM {
+	vm := New()
+	vm.RegisterFunction("double", doubleFunction("double"))
+	vm.RegisterBuiltin("strtoupper", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return types.NewString(strings.ToUpper(args[0].ToString())), nil
+	})
+
+	class := types.NewClassEntry("Math")
+	class.Methods["twice"] = doubleMethod("twice", false)
+	class.Methods["staticTwice"] = doubleMethod("staticTwice", true)
+	class.Methods["__invoke"] = doubleMethod("__invoke", false)
+	vm.classes["Math"] = class
+	return vm
+}
+
+func callableArray(first *types.Value, method string) *types.Value {
+	return types.NewArray(types.NewArrayFromSlice([]*types.Value{first, types.NewString(method)}))
+}
+
+// ============================================================================
+// CallCallable Tests
+// ============================================================================
+
+func TestCallCallable_Forms(t *testing.T) {
+	vm := newCallableTestVM()
+	obj := types.NewObject(types.NewObjectFromClass(vm.classes["Math"]))
+
+	tests := []struct {
+		name     string
+		callable *types.Value
+	}{
+		{"user function", types.NewString("double")},
+		{"leading backslash", types.NewString("\\double")},
+		{"static method string", types.NewString("Math::staticTwice")},
+		{"object method array", callableArray(obj, "twice")},
+		{"class method array", callableArray(types.NewString("Math"), "staticTwice")},
+		{"invokable object", obj},
+	}
+
+	for _, tt := range tests {
+		result, err := vm.CallCallable(tt.callable, []*types.Value{types.NewInt(21)})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if result.ToInt() != 42 {
+			t.Errorf("%s: expected 42, got %v", tt.name, result)
+		}
+	}
+}
+
+func TestCallCallable_Builtin(t *testing.T) {
+	vm := newCallableTestVM()
+
+	result, err := vm.CallCallable(types.NewString("STRTOUPPER"), []*types.Value{types.NewString("abc")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ToString() != "ABC" {
+		t.Errorf("Expected 'ABC', got '%s'", result.ToString())
+	}
+}
+
+func TestCallCallable_Errors(t *testing.T) {
+	vm := newCallableTestVM()
+
+	tests := []struct {
+		callable *types.Value
+		expected string
+	}{
+		{types.NewString("missing"), "Call to undefined function missing()"},
+		{types.NewString("Nope::run"), "Class \"Nope\" not found"},
+		{callableArray(types.NewString("Math"), "twice"), "Non-static method Math::twice() cannot be called statically"},
+		{callableArray(types.NewString("Math"), "nothing"), "Call to undefined method Math::nothing()"},
+		{types.NewInt(5), "Value of type integer is not callable"},
+	}
+
+	for _, tt := range tests {
+		_, err := vm.CallCallable(tt.callable, nil)
+		if err == nil {
+			t.Errorf("Expected error %q, got nil", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("Expected error %q, got %q", tt.expected, err.Error())
+		}
+	}
+}
+
+// ============================================================================
+// Callable Builtin Tests
+// ============================================================================
+
+func TestBuiltin_CallUserFunc(t *testing.T) {
+	vm := newCallableTestVM()
+
+	result, err := vm.CallCallable(types.NewString("call_user_func"),
+		[]*types.Value{types.NewString("double"), types.NewInt(5)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ToInt() != 10 {
+		t.Errorf("Expected 10, got %v", result)
+	}
+}
+
+func TestBuiltin_CallUserFuncArray(t *testing.T) {
+	vm := newCallableTestVM()
+
+	args := types.NewArray(types.NewArrayFromSlice([]*types.Value{types.NewInt(7)}))
+	result, err := vm.CallCallable(types.NewString("call_user_func_array"),
+		[]*types.Value{types.NewString("Math::staticTwice"), args})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ToInt() != 14 {
+		t.Errorf("Expected 14, got %v", result)
+	}
+}
+
+func TestBuiltin_IsCallable(t *testing.T) {
+	vm := newCallableTestVM()
+
+	tests := []struct {
+		value    *types.Value
+		expected bool
+	}{
+		{types.NewString("double"), true},
+		{types.NewString("strtoupper"), true},
+		{types.NewString("is_callable"), true},
+		{types.NewString("undefined_fn"), false},
+		{types.NewArray(types.NewEmptyArray()), false},
+		{types.NewNull(), false},
+	}
+
+	for _, tt := range tests {
+		result, err := builtinIsCallable(vm, []*types.Value{tt.value})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.ToBool() != tt.expected {
+			t.Errorf("is_callable(%v): expected %v, got %v", tt.value, tt.expected, result.ToBool())
+		}
+	}
+}
+
+// ============================================================================
+// OpCallableConvert Tests
+// ============================================================================
+
+func TestOpCallableConvert_Function(t *testing.T) {
+	vm := newCallableTestVM()
+	vm.constants = []interface{}{"strtoupper"}
+
+	// $f = strtoupper(...);
+	mainFunc := &CompiledFunction{
+		Name: "main",
+		Instructions: Instructions{
+			{Opcode: OpInitFcall, Op2: Operand{Type: OpConst, Value: 0}},
+			{Opcode: OpCallableConvert, Result: Operand{Type: OpTmpVar, Value: 0}},
+		},
+		NumLocals: 10,
+	}
+
+	frame := NewFrame(mainFunc)
+	vm.pushFrame(frame)
+	if err := vm.runFrame(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	closure := frame.getLocal(0)
+	if !closure.IsObject() || closure.ToObject().ClassName != "Closure" {
+		t.Fatalf("Expected Closure object, got %v", closure)
+	}
+	if frame.pendingCall != nil || frame.pendingParams != nil {
+		t.Error("CALLABLE_CONVERT should consume the pending call")
+	}
+
+	result, err := vm.CallCallable(closure, []*types.Value{types.NewString("php")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ToString() != "PHP" {
+		t.Errorf("Expected 'PHP', got '%s'", result.ToString())
+	}
+}
+
+func TestOpCallableConvert_Method(t *testing.T) {
+	vm := newCallableTestVM()
+	vm.constants = []interface{}{"twice"}
+	obj := types.NewObjectFromClass(vm.classes["Math"])
+
+	// $f = $obj->twice(...);
+	mainFunc := &CompiledFunction{
+		Name: "main",
+		Instructions: Instructions{
+			{Opcode: OpInitMethodCall, Op1: Operand{Type: OpTmpVar, Value: 0}, Op2: Operand{Type: OpConst, Value: 0}},
+			{Opcode: OpCallableConvert, Result: Operand{Type: OpTmpVar, Value: 1}},
+		},
+		NumLocals: 10,
+	}
+
+	frame := NewFrame(mainFunc)
+	frame.setLocal(0, types.NewObject(obj))
+	vm.pushFrame(frame)
+	if err := vm.runFrame(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	closure, ok := frame.getLocal(1).ToObject().Internal.(*Closure)
+	if !ok {
+		t.Fatal("Expected Closure payload on converted callable")
+	}
+	if closure.This != obj {
+		t.Error("Method closure should be bound to the object")
+	}
+
+	result, err := vm.CallCallable(frame.getLocal(1), []*types.Value{types.NewInt(4)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ToInt() != 8 {
+		t.Errorf("Expected 8, got %v", result)
+	}
+}
+
+func TestOpCallableConvert_NoPendingCall(t *testing.T) {
+	vm := New()
+
+	frame := NewFrame(&CompiledFunction{Name: "main", NumLocals: 10})
+	vm.pushFrame(frame)
+
+	err := vm.dispatch(frame, Instruction{Opcode: OpCallableConvert, Result: Operand{Type: OpTmpVar, Value: 0}})
+	if err == nil {
+		t.Fatal("Expected error for CALLABLE_CONVERT without pending call")
+	}
+}
+
+func TestOpInitFcallByName_Closure(t *testing.T) {
+	vm := newCallableTestVM()
+	vm.constants = []interface{}{int64(3)}
+
+	// $f(3) where $f = double(...)
+	closure := newClosureObject(&Closure{Function: vm.functions["double"]})
+	mainFunc := &CompiledFunction{
+		Name: "main",
+		Instructions: Instructions{
+			{Opcode: OpInitFcallByName, Op1: Operand{Type: OpTmpVar, Value: 0}},
+			{Opcode: OpSendVal, Op1: Operand{Type: OpConst, Value: 0}},
+			{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 1}},
+		},
+		NumLocals: 10,
+	}
+
+	frame := NewFrame(mainFunc)
+	frame.setLocal(0, closure)
+	vm.pushFrame(frame)
+	if err := vm.runFrame(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if frame.getLocal(1).ToInt() != 6 {
+		t.Errorf("Expected 6, got %v", frame.getLocal(1))
+	}
+}
+
+// ============================================================================
+// Namespace Tests
+// ============================================================================
+
+func TestInitNsFcallByName_FallsBackToGlobal(t *testing.T) {
+	vm := newCallableTestVM()
+	vm.RegisterFunction("App\\double", doubleFunction("App\\double"))
+	vm.constants = []interface{}{"App\\double", "double", "App\\strtoupper", "strtoupper", "abc", int64(4)}
+
+	call := func(name, fallback, arg uint32) Instructions {
+		return Instructions{
+			{Opcode: OpInitNsFcallByName, Op1: Operand{Type: OpConst, Value: name}, Op2: Operand{Type: OpConst, Value: fallback}},
+			{Opcode: OpSendVal, Op1: Operand{Type: OpConst, Value: arg}},
+			{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 0}},
+			{Opcode: OpEcho, Op1: Operand{Type: OpTmpVar, Value: 0}},
+		}
+	}
+
+	instrs := append(call(0, 1, 5), call(2, 3, 4)...)
+	if err := runMain(vm, &CompiledFunction{Name: "main", Instructions: instrs, NumLocals: 10}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm.GetOutput() != "8ABC" {
+		t.Errorf("Expected namespaced double and global strtoupper, got %q", vm.GetOutput())
+	}
+}
+
+func TestLookupClass_FullyQualifiedAndCaseInsensitive(t *testing.T) {
+	vm := New()
+	vm.RegisterClass(types.NewClassEntry("App\\Models\\User"))
+
+	for _, name := range []string{"App\\Models\\User", "\\App\\Models\\User", "app\\models\\user"} {
+		if class, ok := vm.lookupClass(name); !ok || class.Name != "App\\Models\\User" {
+			t.Errorf("lookupClass(%q) failed", name)
+		}
+	}
+	if _, ok := vm.lookupClass("User"); ok {
+		t.Error("Unqualified name should not match a namespaced class")
+	}
+}
@@ -0,0 +1,280 @@
+package vm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Function Profiling
+// ============================================================================
+
+// FunctionStat holds the counters of one function
+type FunctionStat struct {
+	Name      string        // Function, Class::method, php::builtin or {main}
+	File      string        // File declaring it ("php:internal" for builtins)
+	Line      int           // Line it starts on
+	Calls     uint64        // Number of calls
+	Inclusive time.Duration // Wall time including the calls it made
+	Exclusive time.Duration // Wall time excluding the calls it made
+	Memory    int64         // Change of the heap size during the calls, including nested calls
+}
+
+// callEdge identifies calls from a function to another on a line
+type callEdge struct {
+	caller, callee string
+	line           int
+}
+
+// callStat holds the counters of calls along an edge
+type callStat struct {
+	calls  uint64
+	time   time.Duration
+	memory int64
+}
+
+// profileEntry is a call being executed
+type profileEntry struct {
+	stat     *FunctionStat
+	line     int // Line of the caller making the call
+	start    time.Time
+	memory   int64
+	children time.Duration // Inclusive time of the calls it made
+	stack    string        // Folded stack down to the call
+}
+
+// CallProfiler measures the wall time, calls and memory of each function,
+// and of each call between functions, for callgrind files and flame graphs.
+// The VM reports the calls to it as frames are pushed and popped and as
+// builtins run.
+type CallProfiler struct {
+	functions map[string]*FunctionStat
+	order     []string // Functions in the order first called
+	edges     map[callEdge]*callStat
+	folded    map[string]time.Duration // Exclusive time by folded stack
+	stack     []*profileEntry
+	active    map[string]int // Calls of each function in the stack, for recursion
+	now       func() time.Time
+	memory    func() int64
+}
+
+// NewCallProfiler creates an empty function profiler
+func NewCallProfiler() *CallProfiler {
+	return &CallProfiler{
+		functions: make(map[string]*FunctionStat),
+		edges:     make(map[callEdge]*callStat),
+		folded:    make(map[string]time.Duration),
+		active:    make(map[string]int),
+		now:       time.Now,
+		memory:    heapInUse,
+	}
+}
+
+// EnableCallProfiling turns on the measurement of function calls
+func (vm *VM) EnableCallProfiling() *CallProfiler {
+	if vm.callProfiler == nil {
+		vm.callProfiler = NewCallProfiler()
+	}
+	return vm.callProfiler
+}
+
+// CallProfile returns the active function profiler (nil when disabled)
+func (vm *VM) CallProfile() *CallProfiler {
+	return vm.callProfiler
+}
+
+// enterFrame records the start of the function a frame just pushed runs
+func (p *CallProfiler) enterFrame(vm *VM, frame *Frame) {
+	name := frameFunction(frame)
+	if frame.fn.Name == "main" {
+		// Script op arrays: the main script, or an included file
+		if vm.frameIndex == 0 {
+			name = "{main}"
+		} else {
+			name = "include::" + vm.frameFile(frame)
+		}
+	}
+	line := 0
+	for _, instr := range frame.fn.Instructions {
+		if instr.Lineno > 0 {
+			line = int(instr.Lineno)
+			break
+		}
+	}
+	p.enter(name, vm.frameFile(frame), line, vm.callerLine(vm.frameIndex-1))
+}
+
+// enterBuiltin records the start of a builtin function
+func (p *CallProfiler) enterBuiltin(vm *VM, name string) {
+	p.enter("php::"+name, "php:internal", 0, vm.callerLine(vm.frameIndex))
+}
+
+// callerLine returns the line the frame at an index executes, 0 if none
+func (vm *VM) callerLine(index int) int {
+	if index < 0 || index > vm.frameIndex {
+		return 0
+	}
+	frame := vm.frames[index]
+	if frame.ip > 0 && frame.ip <= len(frame.fn.Instructions) {
+		return int(frame.fn.Instructions[frame.ip-1].Lineno)
+	}
+	return 0
+}
+
+// enter records the start of a call
+func (p *CallProfiler) enter(name, file string, line, callLine int) {
+	stat, ok := p.functions[name]
+	if !ok {
+		stat = &FunctionStat{Name: name, File: file, Line: line}
+		p.functions[name] = stat
+		p.order = append(p.order, name)
+	}
+	stack := name
+	if len(p.stack) > 0 {
+		stack = p.stack[len(p.stack)-1].stack + ";" + name
+	}
+	p.active[name]++
+	p.stack = append(p.stack, &profileEntry{
+		stat:   stat,
+		line:   callLine,
+		start:  p.now(),
+		memory: p.memory(),
+		stack:  stack,
+	})
+}
+
+// leave records the end of the innermost call
+func (p *CallProfiler) leave() {
+	if len(p.stack) == 0 {
+		return
+	}
+	entry := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	elapsed := p.now().Sub(entry.start)
+	memory := p.memory() - entry.memory
+
+	stat := entry.stat
+	stat.Calls++
+	stat.Exclusive += elapsed - entry.children
+	p.folded[entry.stack] += elapsed - entry.children
+	// A recursive call's time is already in the outermost one's
+	p.active[stat.Name]--
+	if p.active[stat.Name] == 0 {
+		stat.Inclusive += elapsed
+		stat.Memory += memory
+	}
+
+	if len(p.stack) > 0 {
+		caller := p.stack[len(p.stack)-1]
+		caller.children += elapsed
+		edge := callEdge{caller: caller.stat.Name, callee: stat.Name, line: entry.line}
+		call, ok := p.edges[edge]
+		if !ok {
+			call = &callStat{}
+			p.edges[edge] = call
+		}
+		call.calls++
+		call.time += elapsed
+		call.memory += memory
+	}
+}
+
+// Functions returns the counters of the functions, by inclusive time
+func (p *CallProfiler) Functions() []FunctionStat {
+	stats := make([]FunctionStat, 0, len(p.functions))
+	for _, name := range p.order {
+		stats = append(stats, *p.functions[name])
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Inclusive > stats[j].Inclusive })
+	return stats
+}
+
+// WriteCallgrind writes the profile in the callgrind format read by
+// KCachegrind and QCachegrind, timed in units of 10ns like Xdebug's. cmd
+// names the profiled command.
+func (p *CallProfiler) WriteCallgrind(w io.Writer, cmd string) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "version: 1\ncreator: php-go\ncmd: %s\npart: 1\npositions: line\n\n", cmd)
+	fmt.Fprintf(out, "events: Time_(10ns) Memory_(bytes)\n\n")
+
+	// Files and functions are named once, then referred to by number
+	files, functions := map[string]int{}, map[string]int{}
+	// Paths are absolute for the viewers to find the sources
+	file := func(path string) string {
+		if abs, err := filepath.Abs(path); err == nil && !strings.HasPrefix(path, "php:") {
+			return abs
+		}
+		return path
+	}
+	name := func(ids map[string]int, s string) string {
+		if id, ok := ids[s]; ok {
+			return fmt.Sprintf("(%d)", id)
+		}
+		ids[s] = len(ids) + 1
+		return fmt.Sprintf("(%d) %s", ids[s], s)
+	}
+
+	calls := make(map[string][]callEdge)
+	for edge := range p.edges {
+		calls[edge.caller] = append(calls[edge.caller], edge)
+	}
+	var summary time.Duration
+	for _, fn := range p.order {
+		stat := p.functions[fn]
+		fmt.Fprintf(out, "fl=%s\nfn=%s\n", name(files, file(stat.File)), name(functions, stat.Name))
+		fmt.Fprintf(out, "%d %d %d\n", stat.Line, stat.Exclusive/10, stat.Memory-p.calleeMemory(fn))
+		summary += stat.Exclusive
+
+		edges := calls[fn]
+		sort.Slice(edges, func(i, j int) bool {
+			if edges[i].line != edges[j].line {
+				return edges[i].line < edges[j].line
+			}
+			return edges[i].callee < edges[j].callee
+		})
+		for _, edge := range edges {
+			callee, call := p.functions[edge.callee], p.edges[edge]
+			fmt.Fprintf(out, "cfl=%s\ncfn=%s\n", name(files, file(callee.File)), name(functions, callee.Name))
+			fmt.Fprintf(out, "calls=%d %d\n%d %d %d\n", call.calls, callee.Line, edge.line, call.time/10, call.memory)
+		}
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintf(out, "summary: %d 0\n", summary/10)
+	return out.Flush()
+}
+
+// calleeMemory returns the memory change of the calls a function made
+func (p *CallProfiler) calleeMemory(fn string) int64 {
+	var memory int64
+	for edge, call := range p.edges {
+		if edge.caller == fn {
+			memory += call.memory
+		}
+	}
+	return memory
+}
+
+// WriteFolded writes the profile as folded stacks for flame graphs
+// (flamegraph.pl, speedscope, inferno): a line "{main};f;g 1234" per stack,
+// with the nanoseconds spent in its innermost function
+func (p *CallProfiler) WriteFolded(w io.Writer) error {
+	stacks := make([]string, 0, len(p.folded))
+	for stack, elapsed := range p.folded {
+		if elapsed > 0 {
+			stacks = append(stacks, stack)
+		}
+	}
+	sort.Strings(stacks)
+
+	out := bufio.NewWriter(w)
+	for _, stack := range stacks {
+		// Spaces would end the stack
+		fmt.Fprintf(out, "%s %d\n", strings.ReplaceAll(stack, " ", "_"), p.folded[stack].Nanoseconds())
+	}
+	return out.Flush()
+}
@@ -0,0 +1,116 @@
+package vm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// profileProgram runs double(4) twice and strlen("abc") under a call
+// profiler with a fake clock and heap
+func profileProgram(t *testing.T) *CallProfiler {
+	t.Helper()
+	vm := New()
+	vm.constants = []interface{}{"double", int64(4), "strlen", "abc"}
+	double := doubleFunction("double")
+	double.Instructions[0].Lineno = 10
+	vm.RegisterFunction("double", double)
+	profiler := vm.EnableCallProfiling()
+	profiler.now = fakeClock(time.Microsecond)
+	heap := int64(0)
+	profiler.memory = func() int64 {
+		heap += 8
+		return heap
+	}
+
+	call := func(name, arg uint32, line uint32) []Instruction {
+		return []Instruction{
+			{Opcode: OpInitFcall, Op2: Operand{Type: OpConst, Value: name}, Lineno: line},
+			{Opcode: OpSendVal, Op1: Operand{Type: OpConst, Value: arg}, Lineno: line},
+			{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 0}, Lineno: line},
+		}
+	}
+	var program Instructions
+	program = append(program, call(0, 1, 2)...)
+	program = append(program, call(0, 1, 3)...)
+	program = append(program, call(2, 3, 4)...)
+	if err := vm.Execute(program); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return profiler
+}
+
+func TestCallProfiler_Counters(t *testing.T) {
+	profiler := profileProgram(t)
+	stats := map[string]FunctionStat{}
+	var exclusive time.Duration
+	for _, stat := range profiler.Functions() {
+		stats[stat.Name] = stat
+		exclusive += stat.Exclusive
+	}
+
+	main, double, strlen := stats["{main}"], stats["double"], stats["php::strlen"]
+	if main.Calls != 1 || double.Calls != 2 || strlen.Calls != 1 {
+		t.Fatalf("Unexpected call counts %+v", stats)
+	}
+	if double.Line != 10 || strlen.File != "php:internal" {
+		t.Errorf("Unexpected function positions %+v %+v", double, strlen)
+	}
+	if profiler.Functions()[0].Name != "{main}" || main.Inclusive != exclusive {
+		t.Errorf("Expected {main} to include all the time %v, got %v", exclusive, main.Inclusive)
+	}
+	if double.Inclusive != double.Exclusive || double.Inclusive <= 0 {
+		t.Errorf("Expected double() to call nothing, got %+v", double)
+	}
+	if main.Memory <= double.Memory || double.Memory <= 0 {
+		t.Errorf("Expected the memory of {main} to include its calls, got %d and %d", main.Memory, double.Memory)
+	}
+}
+
+func TestCallProfiler_Recursion(t *testing.T) {
+	profiler := NewCallProfiler()
+	profiler.now = fakeClock(time.Microsecond)
+	profiler.memory = func() int64 { return 0 }
+	profiler.enter("f", "a.php", 1, 0)
+	profiler.enter("f", "a.php", 1, 2)
+	profiler.leave()
+	profiler.leave()
+
+	stat := profiler.Functions()[0]
+	if stat.Calls != 2 || stat.Inclusive != 3*time.Microsecond || stat.Exclusive != 3*time.Microsecond {
+		t.Errorf("Expected the recursive call to be counted once in the inclusive time, got %+v", stat)
+	}
+}
+
+func TestCallProfiler_Output(t *testing.T) {
+	profiler := profileProgram(t)
+
+	var callgrind bytes.Buffer
+	if err := profiler.WriteCallgrind(&callgrind, "test.php"); err != nil {
+		t.Fatal(err)
+	}
+	out := callgrind.String()
+	for _, want := range []string{
+		"events: Time_(10ns) Memory_(bytes)\n",
+		"fn=(1) {main}\n",
+		"cfn=(2) double\ncalls=1 10\n2 ",
+		"cfn=(2)\ncalls=1 10\n3 ",
+		"cfl=(2) php:internal\ncfn=(3) php::strlen\ncalls=1 0\n4 ",
+		"fl=(2)\nfn=(3)\n",
+		"summary: ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the callgrind profile to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	var folded bytes.Buffer
+	if err := profiler.WriteFolded(&folded); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(folded.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "{main} ") || !strings.HasPrefix(lines[1], "{main};double ") || !strings.HasPrefix(lines[2], "{main};php::strlen ") {
+		t.Errorf("Unexpected folded stacks %q", lines)
+	}
+}
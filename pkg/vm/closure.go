@@ -0,0 +1,206 @@
+package vm
+
+import (
+	"maps"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// The Closure Class
+// ============================================================================
+
+// closureClass is the class of Closure objects. It has no state of its own
+// (the closure is the object's Internal payload), so all VMs share it.
+var closureClass *types.ClassEntry
+
+// init builds the class once its methods, which create closures, can
+// refer to it
+func init() {
+	closureClass = newClosureClass()
+}
+
+// newClosureClass builds the final Closure class
+func newClosureClass() *types.ClassEntry {
+	class := types.NewClassEntry("Closure")
+	class.IsFinal = true
+
+	addNativeMethod(class, "__construct", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return nil, vm.ThrowError("Error", "Instantiation of class Closure is not allowed")
+	})
+	class.Constructor = class.Methods["__construct"]
+	class.Constructor.IsConstructor = true
+	class.Constructor.Visibility = types.VisibilityPrivate
+
+	addClosureMethod(class, "__invoke", -1, func(vm *VM, closure *Closure, args []*types.Value) (*types.Value, error) {
+		return vm.invokeTarget(closure.target(), args)
+	})
+	addClosureMethod(class, "bindTo", 2, func(vm *VM, closure *Closure, args []*types.Value) (*types.Value, error) {
+		return vm.closureBind("Closure::bindTo", closure, derefArgs(args), 1)
+	})
+	addClosureMethod(class, "call", -1, func(vm *VM, closure *Closure, args []*types.Value) (*types.Value, error) {
+		if len(args) == 0 || !args[0].Deref().IsObject() {
+			given := "null"
+			if len(args) > 0 {
+				given = args[0].Deref().TypeName()
+			}
+			return nil, vm.ThrowError("TypeError", "Closure::call(): Argument #1 ($newThis) must be of type object, %s given", given)
+		}
+		newThis := args[0].Deref().ToObject()
+		bound := vm.bindClosure(closure, newThis, newThis.ClassEntry)
+		if bound == nil {
+			return types.NewNull(), nil
+		}
+		return vm.invokeTarget(bound.target(), args[1:])
+	})
+
+	addNativeMethod(class, "bind", 3, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		args = derefArgs(args)
+		var closure *Closure
+		if len(args) > 0 && args[0].IsObject() {
+			closure, _ = args[0].ToObject().Internal.(*Closure)
+		}
+		if closure == nil {
+			given := "null"
+			if len(args) > 0 {
+				given = args[0].TypeName()
+			}
+			return nil, vm.ThrowError("TypeError", "Closure::bind(): Argument #1 ($closure) must be of type Closure, %s given", given)
+		}
+		return vm.closureBind("Closure::bind", closure, args[1:], 2)
+	}).IsStatic = true
+	addNativeMethod(class, "fromCallable", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		if len(args) == 0 {
+			return nil, vm.ThrowError("ArgumentCountError", "Closure::fromCallable() expects exactly 1 argument, 0 given")
+		}
+		callable := args[0].Deref()
+		if callable.IsObject() {
+			if _, ok := callable.ToObject().Internal.(*Closure); ok {
+				return callable, nil
+			}
+		}
+		target, err := vm.resolveCallable(callable)
+		if err != nil {
+			return nil, vm.ThrowError("TypeError", "Closure::fromCallable(): Argument #1 ($callback) must be a valid callback, %s", err.Error())
+		}
+		return newClosureObject(closureFromTarget(target)), nil
+	}).IsStatic = true
+	return class
+}
+
+// addClosureMethod adds an instance method of Closure
+func addClosureMethod(class *types.ClassEntry, name string, numParams int, fn func(vm *VM, closure *Closure, args []*types.Value) (*types.Value, error)) *types.MethodDef {
+	return addNativeMethod(class, name, numParams, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		closure, ok := this.Internal.(*Closure)
+		if !ok {
+			return nil, vm.ThrowError("Error", "Closure object is not initialized")
+		}
+		return fn(vm, closure, args)
+	})
+}
+
+// closureBind implements Closure::bind() and Closure::bindTo(): args are
+// the new $this and the new scope, a class name or object, which defaults
+// to "static" (the current scope). Bindings PHP rejects are reported as
+// warnings and return null. position is that of the $newThis argument.
+func (vm *VM) closureBind(method string, closure *Closure, args []*types.Value, position int) (*types.Value, error) {
+	var newThis *types.Object
+	if len(args) > 0 && !args[0].IsNull() {
+		if !args[0].IsObject() {
+			return nil, vm.ThrowError("TypeError", "%s(): Argument #%d ($newThis) must be of type ?object, %s given", method, position, args[0].TypeName())
+		}
+		newThis = args[0].ToObject()
+	}
+
+	scope := closure.Scope
+	if len(args) > 1 {
+		switch newScope := args[1]; {
+		case newScope.IsNull():
+			scope = nil
+		case newScope.IsObject():
+			scope = newScope.ToObject().ClassEntry
+		case newScope.ToString() != "static":
+			name := strings.TrimPrefix(newScope.ToString(), "\\")
+			class, exists, err := vm.loadClass(name)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				vm.warning("Class \"%s\" not found", name)
+				return types.NewNull(), nil
+			}
+			scope = class
+		}
+	}
+
+	bound := vm.bindClosure(closure, newThis, scope)
+	if bound == nil {
+		return types.NewNull(), nil
+	}
+	return newClosureObject(bound), nil
+}
+
+// bindClosure returns a copy of a closure bound to another $this (nil to
+// unbind it) and class scope, whose called class becomes the class of the
+// new $this or else the scope. Like PHP it refuses, with a warning and a
+// nil result, to bind static closures to an object, to unbind $this from
+// the closures using it and from methods, and to change the scope of
+// closures created from functions and methods.
+func (vm *VM) bindClosure(closure *Closure, newThis *types.Object, scope *types.ClassEntry) *Closure {
+	method := closure.Fake && closure.Scope != nil
+	switch {
+	case newThis != nil && closure.Static:
+		vm.warning("Cannot bind an instance to a static closure")
+		return nil
+	case newThis != nil && method && !vm.isInstanceOf(newThis.ClassEntry, closure.Scope.Name):
+		vm.warning("Cannot bind method %s::%s() to object of class %s", closure.Scope.Name, closure.target().Name, newThis.ClassName)
+		return nil
+	case newThis == nil && method && !closure.Static:
+		vm.warning("Cannot unbind $this of method")
+		return nil
+	case newThis == nil && !closure.Fake && closure.This != nil && usesThis(closure.Function):
+		vm.warning("Cannot unbind $this of closure using $this")
+		return nil
+	case closure.Fake && scope != closure.Scope:
+		if closure.Scope == nil {
+			vm.warning("Cannot rebind scope of closure created from function")
+		} else {
+			vm.warning("Cannot rebind scope of closure created from method")
+		}
+		return nil
+	}
+
+	bound := *closure
+	bound.This = newThis
+	bound.Scope = scope
+	bound.CalledClass = scope
+	if newThis != nil {
+		bound.CalledClass = newThis.ClassEntry
+	}
+	if closure.Function != nil && !closure.Fake {
+		fn := *closure.Function
+		bound.Function = &fn
+	}
+	bound.CapturedVars = maps.Clone(closure.CapturedVars)
+	if closure.StaticVars != nil {
+		bound.StaticVars = make(map[string]*types.Value, len(closure.StaticVars))
+		for name, value := range closure.StaticVars {
+			bound.StaticVars[name] = value.Copy()
+		}
+	}
+	return &bound
+}
+
+// usesThis reports whether a closure body refers to $this
+func usesThis(fn *CompiledFunction) bool {
+	if fn == nil {
+		return false
+	}
+	for _, instr := range fn.Instructions {
+		if instr.Opcode == OpFetchThis {
+			return true
+		}
+	}
+	return false
+}
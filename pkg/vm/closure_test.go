@@ -0,0 +1,202 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// thisFunction returns a closure body returning $this
+func thisFunction() *CompiledFunction {
+	return &CompiledFunction{
+		Name: "{closure}",
+		Instructions: Instructions{
+			{Opcode: OpFetchThis, Result: Operand{Type: OpTmpVar, Value: 0}},
+			{Opcode: OpReturn, Op1: Operand{Type: OpTmpVar, Value: 0}},
+		},
+		NumLocals: 10,
+	}
+}
+
+// callClosureMethod calls a method of a Closure object, or a static one
+// given the class name
+func callClosureMethod(t *testing.T, vm *VM, closure *types.Value, method string, args ...*types.Value) *types.Value {
+	t.Helper()
+	result, err := vm.CallCallable(callableArray(closure, method), args)
+	if err != nil {
+		t.Fatalf("%s() failed: %v", method, err)
+	}
+	return result
+}
+
+func TestClosure_Class(t *testing.T) {
+	vm := newCallableTestVM()
+	closure := newClosureObject(&Closure{Function: vm.functions["double"]})
+
+	if class := closure.ToObject().ClassEntry; class != vm.classes["Closure"] || !class.IsFinal {
+		t.Fatalf("Expected the final Closure class, got %v", class)
+	}
+	if got := callClosureMethod(t, vm, closure, "__invoke", types.NewInt(21)).ToInt(); got != 42 {
+		t.Errorf("__invoke(21) = %d", got)
+	}
+
+	_, err := vm.instantiate(vm.classes["Closure"], &CallParams{})
+	expectReflectionError(t, err, "Error", "Instantiation of class Closure is not allowed")
+}
+
+func TestClosure_BindTo(t *testing.T) {
+	vm := newCallableTestVM()
+	vm.SetScriptPath("test.php")
+	obj := types.NewObjectFromClass(vm.classes["Math"])
+	closure := newClosureObject(&Closure{Function: thisFunction(), CapturedVars: map[string]*types.Value{}})
+
+	// Unbound, the closure has no $this
+	if _, err := vm.CallCallable(closure, nil); err == nil || !strings.Contains(err.Error(), "Using $this when not in object context") {
+		t.Errorf("Expected an error using $this, got %v", err)
+	}
+
+	// Binding $this makes its class the called class and keeps the scope
+	bound := callClosureMethod(t, vm, closure, "bindTo", types.NewObject(obj))
+	boundClosure := bound.ToObject().Internal.(*Closure)
+	if boundClosure.This != obj || boundClosure.CalledClass != obj.ClassEntry || boundClosure.Scope != nil {
+		t.Errorf("Expected $this and the called class to be bound, got %+v", boundClosure)
+	}
+	if result, err := vm.CallCallable(bound, nil); err != nil || result.ToObject() != obj {
+		t.Errorf("Expected the bound closure to return $this, got %v (%v)", result, err)
+	}
+	if closure.ToObject().Internal.(*Closure).This != nil {
+		t.Error("Expected bindTo() to leave the closure unbound")
+	}
+
+	// Closure::bind() changes the scope
+	scoped := callClosureMethod(t, vm, types.NewString("Closure"), "bind", closure, types.NewNull(), types.NewString("Math"))
+	if scope := scoped.ToObject().Internal.(*Closure).Scope; scope != vm.classes["Math"] {
+		t.Errorf("Expected the Math scope, got %v", scope)
+	}
+
+	// $this cannot be unbound from a closure using it
+	if result := callClosureMethod(t, vm, bound, "bindTo", types.NewNull()); !result.IsNull() {
+		t.Errorf("Expected null, got %v", result)
+	}
+	if !strings.Contains(vm.GetOutput(), "Warning: Cannot unbind $this of closure using $this") {
+		t.Errorf("Expected a warning, got %q", vm.GetOutput())
+	}
+
+	// Closure::call() binds temporarily
+	other := types.NewObjectFromClass(vm.classes["Math"])
+	if result := callClosureMethod(t, vm, bound, "call", types.NewObject(other)); result.ToObject() != other {
+		t.Errorf("Expected call() to run with the given $this, got %v", result)
+	}
+}
+
+func TestClosure_Static(t *testing.T) {
+	vm := newCallableTestVM()
+	vm.SetScriptPath("test.php")
+	obj := types.NewObject(types.NewObjectFromClass(vm.classes["Math"]))
+	closure := newClosureObject(&Closure{Function: thisFunction(), Static: true})
+
+	if result := callClosureMethod(t, vm, closure, "bindTo", obj); !result.IsNull() {
+		t.Errorf("Expected null, got %v", result)
+	}
+	if result := callClosureMethod(t, vm, closure, "call", obj); !result.IsNull() {
+		t.Errorf("Expected null, got %v", result)
+	}
+	if got := strings.Count(vm.GetOutput(), "Warning: Cannot bind an instance to a static closure"); got != 2 {
+		t.Errorf("Expected 2 warnings, got %q", vm.GetOutput())
+	}
+
+	// Static closures can still change scope
+	scoped := callClosureMethod(t, vm, closure, "bindTo", types.NewNull(), types.NewString("Math"))
+	if scoped.IsNull() || scoped.ToObject().Internal.(*Closure).Scope != vm.classes["Math"] {
+		t.Errorf("Expected a closure scoped to Math, got %v", scoped)
+	}
+}
+
+func TestClosure_FromCallable(t *testing.T) {
+	vm := newCallableTestVM()
+	vm.SetScriptPath("test.php")
+	fromCallable := types.NewString("Closure::fromCallable")
+	obj := types.NewObject(types.NewObjectFromClass(vm.classes["Math"]))
+
+	for _, callable := range []*types.Value{
+		types.NewString("double"),
+		types.NewString("Math::staticTwice"),
+		callableArray(obj, "twice"),
+		obj,
+	} {
+		closure, err := vm.CallCallable(fromCallable, []*types.Value{callable})
+		if err != nil {
+			t.Fatalf("fromCallable(%v) failed: %v", callable, err)
+		}
+		if result, err := vm.CallCallable(closure, []*types.Value{types.NewInt(21)}); err != nil || result.ToInt() != 42 {
+			t.Errorf("fromCallable(%v)(21) = %v (%v)", callable, result, err)
+		}
+	}
+
+	// Closures are returned as they are
+	closure := newClosureObject(&Closure{Function: vm.functions["double"]})
+	if result, _ := vm.CallCallable(fromCallable, []*types.Value{closure}); result.ToObject() != closure.ToObject() {
+		t.Error("Expected fromCallable() to return the closure")
+	}
+
+	// Closures of methods keep their $this and scope
+	method, _ := vm.CallCallable(fromCallable, []*types.Value{callableArray(obj, "twice")})
+	if result := callClosureMethod(t, vm, method, "bindTo", types.NewNull()); !result.IsNull() {
+		t.Errorf("Expected null, got %v", result)
+	}
+	function, _ := vm.CallCallable(fromCallable, []*types.Value{types.NewString("double")})
+	if result := callClosureMethod(t, vm, function, "bindTo", types.NewNull(), types.NewString("Math")); !result.IsNull() {
+		t.Errorf("Expected null, got %v", result)
+	}
+	for _, warning := range []string{"Cannot unbind $this of method", "Cannot rebind scope of closure created from function"} {
+		if !strings.Contains(vm.GetOutput(), "Warning: "+warning) {
+			t.Errorf("Expected warning %q, got %q", warning, vm.GetOutput())
+		}
+	}
+
+	_, err := vm.CallCallable(fromCallable, []*types.Value{types.NewString("missing")})
+	expectReflectionError(t, err, "TypeError", "Closure::fromCallable(): Argument #1 ($callback) must be a valid callback, Call to undefined function missing()")
+}
+
+func TestReflectionFunction_ClosureScope(t *testing.T) {
+	vm := newCallableTestVM()
+	obj := types.NewObjectFromClass(vm.classes["Math"])
+	closure := newClosureObject(&Closure{
+		Function:     thisFunction(),
+		This:         obj,
+		Scope:        vm.classes["Math"],
+		CalledClass:  vm.classes["Math"],
+		CapturedVars: map[string]*types.Value{"b": types.NewInt(2), "a": types.NewInt(1)},
+	})
+
+	reflected := reflectionOf(t, vm, "ReflectionFunction", closure)
+	if got := callReflectionMethod(t, vm, reflected, "getClosureThis"); got.ToObject() != obj {
+		t.Errorf("getClosureThis() = %v", got)
+	}
+	for _, method := range []string{"getClosureScopeClass", "getClosureCalledClass"} {
+		class := callReflectionMethod(t, vm, reflected, method)
+		if name := callReflectionMethod(t, vm, class, "getName").ToString(); name != "Math" {
+			t.Errorf("%s() = %s", method, name)
+		}
+	}
+	var names []string
+	callReflectionMethod(t, vm, reflected, "getClosureUsedVariables").ToArray().Each(func(key, value *types.Value) bool {
+		names = append(names, key.ToString())
+		return true
+	})
+	if strings.Join(names, ",") != "a,b" {
+		t.Errorf("getClosureUsedVariables() keys = %v", names)
+	}
+
+	// Static closures have no $this, functions no closure scope
+	static := reflectionOf(t, vm, "ReflectionFunction", newClosureObject(&Closure{Function: thisFunction(), Static: true}))
+	if !callReflectionMethod(t, vm, static, "isStatic").ToBool() || !callReflectionMethod(t, vm, static, "getClosureThis").IsNull() {
+		t.Error("Expected a static closure without $this")
+	}
+	vm.functions["f"] = &CompiledFunction{Name: "f"}
+	function := reflectionOf(t, vm, "ReflectionFunction", types.NewString("f"))
+	if !callReflectionMethod(t, vm, function, "getClosureScopeClass").IsNull() {
+		t.Error("Expected no closure scope for a function")
+	}
+}
@@ -0,0 +1,200 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Global Constants
+// ============================================================================
+
+// DeclareConstClass is the ExtendedValue of a DECLARE_CONST that
+// initializes a class constant whose value is not a constant expression
+const DeclareConstClass = 1
+
+// constantKey returns the key of a constant in the constant table: the
+// namespace part of a name is case-insensitive, the constant name is not
+func constantKey(name string) string {
+	name = strings.TrimPrefix(name, "\\")
+	if i := strings.LastIndex(name, "\\"); i >= 0 {
+		return strings.ToLower(name[:i]) + name[i:]
+	}
+	return name
+}
+
+// DefineConstant defines a global constant. It returns false, leaving the
+// constant unchanged, if it is already defined.
+func (vm *VM) DefineConstant(name string, value *types.Value) bool {
+	key := constantKey(name)
+	if _, exists := vm.LookupConstant(key); exists {
+		return false
+	}
+	vm.definedConstants[key] = value
+	return true
+}
+
+// LookupConstant returns the value of a global constant. true, false and
+// null are the only case-insensitive constants.
+func (vm *VM) LookupConstant(name string) (*types.Value, bool) {
+	key := constantKey(name)
+	if value, ok := vm.definedConstants[key]; ok {
+		return value, true
+	}
+	switch upper := strings.ToUpper(key); upper {
+	case "TRUE", "FALSE", "NULL":
+		return vm.definedConstants[upper], true
+	}
+	return nil, false
+}
+
+// opFetchConstant fetches a global constant
+// Op1: constant name, Op2: global name to fall back to for an unqualified
+// name in a namespace (unused otherwise), Result: the value
+func (vm *VM) opFetchConstant(frame *Frame, instr Instruction) error {
+	name, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	value, ok := vm.LookupConstant(name.ToString())
+	if !ok && instr.Op2.Type != OpUnused {
+		fallback, err := vm.getOperandValue(frame, instr.Op2)
+		if err != nil {
+			return err
+		}
+		value, ok = vm.LookupConstant(fallback.ToString())
+	}
+	if !ok {
+		return vm.ThrowError("Error", "Undefined constant \"%s\"", strings.TrimPrefix(name.ToString(), "\\"))
+	}
+	return vm.setOperandValue(frame, instr.Result, value)
+}
+
+// opDeclareConst declares a global constant: const NAME = value;
+// Op1: constant name, Op2: value
+// With ExtendedValue DeclareConstClass it initializes a class constant
+// instead, once its class is declared:
+// Op1: class name, Op2: constant name, Result: value
+func (vm *VM) opDeclareConst(frame *Frame, instr Instruction) error {
+	if instr.ExtendedValue == DeclareConstClass {
+		return vm.initClassConstant(frame, instr)
+	}
+
+	name, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	value, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+	if !vm.DefineConstant(name.ToString(), assignValue(value.Deref())) {
+		vm.warning("Constant %s already defined", name.ToString())
+	}
+	return nil
+}
+
+// initClassConstant sets the value of a class constant whose initializer
+// is evaluated when the class is declared
+func (vm *VM) initClassConstant(frame *Frame, instr Instruction) error {
+	className, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	constName, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+	value, err := vm.getOperandValue(frame, instr.Result)
+	if err != nil {
+		return err
+	}
+
+	class, ok := vm.lookupClass(className.ToString())
+	if !ok {
+		return fmt.Errorf("DECLARE_CONST: class %s is not declared", className.ToString())
+	}
+	constant, ok := class.Constants[constName.ToString()]
+	if !ok {
+		return fmt.Errorf("DECLARE_CONST: class %s has no constant %s", class.Name, constName.ToString())
+	}
+	constant.Value = assignValue(value.Deref())
+	return nil
+}
+
+// ============================================================================
+// Constant Builtins
+// ============================================================================
+
+// registerConstantBuiltins registers define(), defined() and constant()
+func (vm *VM) registerConstantBuiltins() {
+	vm.RegisterBuiltin("define", builtinDefine)
+	vm.RegisterBuiltin("defined", builtinDefined)
+	vm.RegisterBuiltin("constant", builtinConstant)
+}
+
+// define(string $constant_name, mixed $value, bool $case_insensitive = false): bool
+func builtinDefine(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("define() expects at least 2 arguments, %d given", len(args))
+	}
+	name := args[0].ToString()
+	if strings.Contains(name, "::") {
+		return nil, vm.ThrowError("ValueError", "define(): Argument #1 ($constant_name) cannot be a class constant")
+	}
+	if len(args) > 2 && args[2].ToBool() {
+		vm.warning("define(): Argument #3 ($case_insensitive) is ignored since declaration of case-insensitive constants is no longer supported")
+	}
+	if !vm.DefineConstant(name, assignValue(args[1].Deref())) {
+		vm.warning("Constant %s already defined", name)
+		return types.NewBool(false), nil
+	}
+	return types.NewBool(true), nil
+}
+
+// defined(string $constant_name): bool
+func builtinDefined(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("defined() expects exactly 1 argument, 0 given")
+	}
+	name := args[0].ToString()
+	if className, constName, ok := strings.Cut(name, "::"); ok {
+		class, exists, err := vm.loadClass(className)
+		if err != nil || !exists {
+			return types.NewBool(false), err
+		}
+		_, found := class.GetStaticConstant(constName, false, nil)
+		return types.NewBool(found), nil
+	}
+	_, found := vm.LookupConstant(name)
+	return types.NewBool(found), nil
+}
+
+// constant(string $name): mixed
+func builtinConstant(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("constant() expects exactly 1 argument, 0 given")
+	}
+	name := args[0].ToString()
+	if className, constName, ok := strings.Cut(name, "::"); ok {
+		class, exists, err := vm.loadClass(className)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, vm.ThrowError("Error", "Class \"%s\" not found", strings.TrimPrefix(className, "\\"))
+		}
+		value, found := class.GetStaticConstant(constName, false, nil)
+		if !found {
+			return nil, vm.ThrowError("Error", "Undefined constant %s::%s", class.Name, constName)
+		}
+		return value, nil
+	}
+	value, found := vm.LookupConstant(name)
+	if !found {
+		return nil, vm.ThrowError("Error", "Undefined constant \"%s\"", strings.TrimPrefix(name, "\\"))
+	}
+	return value, nil
+}
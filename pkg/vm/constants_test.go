@@ -0,0 +1,137 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestConstants_DeclareAndFetch(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"App\\LIMIT", int64(42), "APP\\LIMIT", "App\\PHP_EOL", "PHP_EOL", "App\\MISSING"}
+
+	// namespace App; const LIMIT = 42; echo \APP\LIMIT, PHP_EOL, MISSING;
+	err := runMain(vm, &CompiledFunction{Name: "main", NumLocals: 10, Instructions: Instructions{
+		{Opcode: OpDeclareConst, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}},
+		{Opcode: OpFetchConstant, Op1: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpTmpVar, Value: 0}},
+		{Opcode: OpEcho, Op1: Operand{Type: OpTmpVar, Value: 0}},
+		{Opcode: OpFetchConstant, Op1: Operand{Type: OpConst, Value: 3}, Op2: Operand{Type: OpConst, Value: 4}, Result: Operand{Type: OpTmpVar, Value: 0}},
+		{Opcode: OpEcho, Op1: Operand{Type: OpTmpVar, Value: 0}},
+		{Opcode: OpFetchConstant, Op1: Operand{Type: OpConst, Value: 5}, Result: Operand{Type: OpTmpVar, Value: 0}},
+	}})
+
+	if got := vm.GetOutput(); got != "42\n" {
+		t.Errorf("Expected the namespaced constant and the global fallback, got %q", got)
+	}
+	expectThrown(t, err, "Error", "Undefined constant \"App\\MISSING\"")
+}
+
+func TestConstants_CaseSensitivity(t *testing.T) {
+	vm := New()
+	vm.DefineConstant("Limit", types.NewInt(1))
+
+	if _, ok := vm.LookupConstant("LIMIT"); ok {
+		t.Error("Constant names should be case-sensitive")
+	}
+	if value, ok := vm.LookupConstant("\\Limit"); !ok || value.ToInt() != 1 {
+		t.Error("Expected a fully qualified name to find the constant")
+	}
+	if value, ok := vm.LookupConstant("True"); !ok || !value.ToBool() {
+		t.Error("Expected true to be case-insensitive")
+	}
+}
+
+func TestConstants_Redeclare(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"A", int64(1), int64(2)}
+
+	err := runMain(vm, &CompiledFunction{Name: "main", NumLocals: 10, Instructions: Instructions{
+		{Opcode: OpDeclareConst, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}},
+		{Opcode: OpDeclareConst, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 2}},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(vm.GetOutput(), "Warning: Constant A already defined") {
+		t.Errorf("Expected a redeclaration warning, got %q", vm.GetOutput())
+	}
+	if value, _ := vm.LookupConstant("A"); value.ToInt() != 1 {
+		t.Errorf("Expected A to keep its value, got %v", value)
+	}
+}
+
+func TestConstants_ClassConstantInitializer(t *testing.T) {
+	vm := New()
+	class := types.NewClassEntry("Config")
+	class.Constants["LIMIT"] = &types.ClassConstant{Name: "LIMIT", Value: types.NewNull(), Visibility: types.VisibilityPublic}
+	vm.RegisterClass(class)
+	vm.constants = []interface{}{"Config", "LIMIT", int64(10)}
+
+	err := runMain(vm, &CompiledFunction{Name: "main", NumLocals: 10, Instructions: Instructions{
+		{Opcode: OpDeclareConst, ExtendedValue: DeclareConstClass, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpConst, Value: 2}},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, _ := class.GetStaticConstant("LIMIT", false, nil); value.ToInt() != 10 {
+		t.Errorf("Expected Config::LIMIT to be initialized to 10, got %v", value)
+	}
+}
+
+func TestDefine(t *testing.T) {
+	vm := New()
+
+	result, err := builtinDefine(vm, []*types.Value{types.NewString("GREETING"), types.NewString("hello")})
+	if err != nil || !result.ToBool() {
+		t.Fatalf("define() = %v, %v", result, err)
+	}
+	if result, _ := builtinDefine(vm, []*types.Value{types.NewString("GREETING"), types.NewString("again")}); result.ToBool() {
+		t.Error("Expected redefining a constant to fail")
+	}
+	if !strings.Contains(vm.GetOutput(), "Constant GREETING already defined") {
+		t.Errorf("Expected a redefinition warning, got %q", vm.GetOutput())
+	}
+	if value, _ := builtinConstant(vm, []*types.Value{types.NewString("GREETING")}); value.ToString() != "hello" {
+		t.Errorf("Expected constant() to return hello, got %v", value)
+	}
+
+	_, err = builtinDefine(vm, []*types.Value{types.NewString("A::B"), types.NewInt(1)})
+	expectThrown(t, err, "ValueError", "define(): Argument #1 ($constant_name) cannot be a class constant")
+}
+
+func TestDefinedAndConstant_ClassConstants(t *testing.T) {
+	vm := New()
+	class := types.NewClassEntry("Config")
+	class.Constants["LIMIT"] = &types.ClassConstant{Name: "LIMIT", Value: types.NewInt(10), Visibility: types.VisibilityPublic}
+	vm.RegisterClass(class)
+
+	tests := []struct {
+		name    string
+		defined bool
+	}{
+		{"Config::LIMIT", true},
+		{"config::LIMIT", true},
+		{"Config::OTHER", false},
+		{"Missing::LIMIT", false},
+		{"PHP_EOL", true},
+		{"php_eol", false},
+	}
+	for _, tt := range tests {
+		result, err := builtinDefined(vm, []*types.Value{types.NewString(tt.name)})
+		if err != nil {
+			t.Fatalf("defined(%q): unexpected error: %v", tt.name, err)
+		}
+		if result.ToBool() != tt.defined {
+			t.Errorf("defined(%q) = %v, expected %v", tt.name, result.ToBool(), tt.defined)
+		}
+	}
+
+	if value, _ := builtinConstant(vm, []*types.Value{types.NewString("Config::LIMIT")}); value.ToInt() != 10 {
+		t.Errorf("Expected constant(\"Config::LIMIT\") to return 10, got %v", value)
+	}
+	_, err := builtinConstant(vm, []*types.Value{types.NewString("Config::OTHER")})
+	expectThrown(t, err, "Error", "Undefined constant Config::OTHER")
+	_, err = builtinConstant(vm, []*types.Value{types.NewString("NOPE")})
+	expectThrown(t, err, "Error", "Undefined constant \"NOPE\"")
+}
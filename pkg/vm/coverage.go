@@ -0,0 +1,482 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Code Coverage
+// ============================================================================
+
+// Options of xdebug_start_code_coverage()
+const (
+	CoverageUnused      = 1 // Report the executable lines not executed (XDEBUG_CC_UNUSED)
+	CoverageDeadCode    = 2 // Report the lines that cannot execute (XDEBUG_CC_DEAD_CODE)
+	CoverageBranchCheck = 4 // Report branches and paths (XDEBUG_CC_BRANCH_CHECK)
+)
+
+// CodeCoverage records the lines a VM executes per file, from the line
+// numbers of the instructions. The VM consults it before each instruction
+// while it runs (see dispatch).
+type CodeCoverage struct {
+	vm      *VM
+	options int
+	running bool
+	hits    map[string]map[int]int // Times each line was entered, by file
+
+	// Line each frame depth executes, so a line counts once per visit
+	// rather than once per instruction or call it makes
+	visits []debugPosition
+	fn     *CompiledFunction
+	lines  map[int]int // Hits of the file of fn
+}
+
+// FileCoverage is the coverage of one file: its executable lines, with
+// the times each was executed, in line order
+type FileCoverage struct {
+	Path  string
+	Lines []LineCoverage
+}
+
+// LineCoverage is the number of times an executable line was executed
+type LineCoverage struct {
+	Line int
+	Hits int
+}
+
+// Covered returns the number of lines of a file executed at least once
+func (f FileCoverage) Covered() int {
+	covered := 0
+	for _, line := range f.Lines {
+		if line.Hits > 0 {
+			covered++
+		}
+	}
+	return covered
+}
+
+// StartCoverage starts recording the lines executed, keeping the lines
+// recorded since the coverage was last cleared
+func (vm *VM) StartCoverage(options int) *CodeCoverage {
+	if vm.coverage == nil {
+		vm.coverage = &CodeCoverage{vm: vm, hits: make(map[string]map[int]int)}
+	}
+	vm.coverage.options = options
+	vm.coverage.running = true
+	return vm.coverage
+}
+
+// StopCoverage stops recording the lines executed; cleanup discards the
+// lines recorded
+func (vm *VM) StopCoverage(cleanup bool) {
+	if vm.coverage == nil {
+		return
+	}
+	vm.coverage.running = false
+	if cleanup {
+		vm.coverage = nil
+	}
+}
+
+// Coverage returns the coverage recorded, nil if none was started
+func (vm *VM) Coverage() *CodeCoverage {
+	return vm.coverage
+}
+
+// Running reports whether lines are being recorded
+func (c *CodeCoverage) Running() bool {
+	return c.running
+}
+
+// Clear discards the lines recorded
+func (c *CodeCoverage) Clear() {
+	c.hits = make(map[string]map[int]int)
+	c.visits, c.fn, c.lines = nil, nil, nil
+}
+
+// record counts the line of an instruction when the frame enters it
+func (c *CodeCoverage) record(frame *Frame, instr Instruction) {
+	line := int(instr.Lineno)
+	if line == 0 {
+		return
+	}
+	depth := c.vm.frameIndex
+	for len(c.visits) <= depth {
+		c.visits = append(c.visits, debugPosition{})
+	}
+	if c.visits[depth] == (debugPosition{frame: frame, line: line}) {
+		return
+	}
+	c.visits[depth] = debugPosition{frame: frame, line: line}
+	if frame.fn != c.fn || c.lines == nil {
+		file := c.vm.frameFile(frame)
+		if c.lines = c.hits[file]; c.lines == nil {
+			c.lines = make(map[int]int)
+			c.hits[file] = c.lines
+		}
+	}
+	c.fn = frame.fn
+	c.lines[line]++
+}
+
+// Executed returns the times each line recorded was executed, by file
+func (c *CodeCoverage) Executed() map[string]map[int]int {
+	return c.hits
+}
+
+// Report returns the coverage of the files the VM loaded and of those
+// with lines recorded, in path order. The lines of the files are the
+// executable ones, executed or not.
+func (c *CodeCoverage) Report() []FileCoverage {
+	lines := c.vm.ExecutableLines()
+	for file, hits := range c.hits {
+		if lines[file] == nil {
+			lines[file] = make(map[int]bool)
+		}
+		for line := range hits {
+			lines[file][line] = true
+		}
+	}
+
+	report := make([]FileCoverage, 0, len(lines))
+	for file, executable := range lines {
+		coverage := FileCoverage{Path: file}
+		for line := range executable {
+			coverage.Lines = append(coverage.Lines, LineCoverage{Line: line, Hits: c.hits[file][line]})
+		}
+		sort.Slice(coverage.Lines, func(i, j int) bool { return coverage.Lines[i].Line < coverage.Lines[j].Line })
+		report = append(report, coverage)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Path < report[j].Path })
+	return report
+}
+
+// ============================================================================
+// Executable Lines
+// ============================================================================
+
+// loadScript remembers the op array of a script run or included, for its
+// executable lines
+func (vm *VM) loadScript(fn *CompiledFunction, path string) {
+	if fn.File != "" {
+		path = fn.File
+	}
+	if path == "" {
+		return
+	}
+	if vm.loadedScripts == nil {
+		vm.loadedScripts = make(map[string]*CompiledFunction)
+	}
+	vm.loadedScripts[path] = fn
+}
+
+// ExecutableLines returns the lines holding instructions of the scripts
+// loaded, by file, including the functions and methods they declare
+func (vm *VM) ExecutableLines() map[string]map[int]bool {
+	lines := make(map[string]map[int]bool)
+	add := func(file string, line uint32) {
+		if line == 0 || file == "" {
+			return
+		}
+		if lines[file] == nil {
+			lines[file] = make(map[int]bool)
+		}
+		lines[file][int(line)] = true
+	}
+
+	seen := make(map[*CompiledFunction]bool)
+	var addFunction func(fn *CompiledFunction, file string)
+	addFunction = func(fn *CompiledFunction, file string) {
+		if seen[fn] {
+			return
+		}
+		seen[fn] = true
+		if fn.File != "" {
+			file = fn.File
+		}
+		for _, instr := range fn.Instructions {
+			add(file, instr.Lineno)
+		}
+		for _, constant := range fn.Constants {
+			switch decl := constant.(type) {
+			case *FunctionDecl:
+				addFunction(decl.Function, file)
+			case *ClassDecl:
+				for _, method := range decl.Class.Methods {
+					methodFile := file
+					if method.File != "" {
+						methodFile = method.File
+					}
+					for _, instr := range method.Instructions {
+						if instr, ok := instr.(Instruction); ok {
+							add(methodFile, instr.Lineno)
+						}
+					}
+				}
+			}
+		}
+	}
+	for path, fn := range vm.loadedScripts {
+		addFunction(fn, path)
+	}
+	return lines
+}
+
+// ============================================================================
+// Reports
+// ============================================================================
+
+// WriteLcov writes the coverage in the lcov tracefile format read by
+// genhtml and most coverage services
+func (c *CodeCoverage) WriteLcov(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("TN:\n")
+	for _, file := range c.Report() {
+		fmt.Fprintf(&buf, "SF:%s\n", absolutePath(file.Path))
+		for _, line := range file.Lines {
+			fmt.Fprintf(&buf, "DA:%d,%d\n", line.Line, line.Hits)
+		}
+		fmt.Fprintf(&buf, "LF:%d\nLH:%d\nend_of_record\n", len(file.Lines), file.Covered())
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// cloverMetrics are the counters of a clover file or project
+type cloverMetrics struct {
+	Files             int `xml:"files,attr,omitempty"`
+	Loc               int `xml:"loc,attr"`
+	Ncloc             int `xml:"ncloc,attr"`
+	Statements        int `xml:"statements,attr"`
+	CoveredStatements int `xml:"coveredstatements,attr"`
+	Elements          int `xml:"elements,attr"`
+	CoveredElements   int `xml:"coveredelements,attr"`
+}
+
+type cloverLine struct {
+	Num   int    `xml:"num,attr"`
+	Type  string `xml:"type,attr"`
+	Count int    `xml:"count,attr"`
+}
+
+type cloverFile struct {
+	Name    string        `xml:"name,attr"`
+	Lines   []cloverLine  `xml:"line"`
+	Metrics cloverMetrics `xml:"metrics"`
+}
+
+type cloverReport struct {
+	XMLName   xml.Name `xml:"coverage"`
+	Generated int64    `xml:"generated,attr"`
+	Project   struct {
+		Timestamp int64         `xml:"timestamp,attr"`
+		Files     []cloverFile  `xml:"file"`
+		Metrics   cloverMetrics `xml:"metrics"`
+	} `xml:"project"`
+}
+
+// WriteClover writes the coverage as a clover XML report, the format CI
+// servers read from PHPUnit's --coverage-clover
+func (c *CodeCoverage) WriteClover(w io.Writer, generated time.Time) error {
+	report := cloverReport{Generated: generated.Unix()}
+	report.Project.Timestamp = generated.Unix()
+	project := &report.Project.Metrics
+	for _, file := range c.Report() {
+		entry := cloverFile{Name: absolutePath(file.Path)}
+		for _, line := range file.Lines {
+			entry.Lines = append(entry.Lines, cloverLine{Num: line.Line, Type: "stmt", Count: line.Hits})
+		}
+		loc := countLines(file)
+		entry.Metrics = cloverMetrics{
+			Loc:               loc,
+			Ncloc:             loc,
+			Statements:        len(file.Lines),
+			CoveredStatements: file.Covered(),
+			Elements:          len(file.Lines),
+			CoveredElements:   file.Covered(),
+		}
+		report.Project.Files = append(report.Project.Files, entry)
+
+		project.Files++
+		project.Loc += entry.Metrics.Loc
+		project.Ncloc += entry.Metrics.Ncloc
+		project.Statements += entry.Metrics.Statements
+		project.CoveredStatements += entry.Metrics.CoveredStatements
+		project.Elements += entry.Metrics.Elements
+		project.CoveredElements += entry.Metrics.CoveredElements
+	}
+
+	out, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
+
+// countLines returns the number of lines of a covered file, the last
+// executable line when it cannot be read
+func countLines(file FileCoverage) int {
+	if data, err := os.ReadFile(file.Path); err == nil {
+		count := bytes.Count(data, []byte("\n"))
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			count++
+		}
+		return count
+	}
+	if len(file.Lines) == 0 {
+		return 0
+	}
+	return file.Lines[len(file.Lines)-1].Line
+}
+
+// absolutePath returns the absolute form of a path, the path itself when
+// it cannot be resolved
+func absolutePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// ============================================================================
+// Coverage Builtins
+// ============================================================================
+
+// registerCoverageBuiltins registers the Xdebug and phpdbg code coverage
+// functions PHPUnit drives its coverage with
+func (vm *VM) registerCoverageBuiltins() {
+	vm.RegisterBuiltin("xdebug_start_code_coverage", builtinXdebugStartCodeCoverage)
+	vm.RegisterBuiltin("xdebug_stop_code_coverage", builtinXdebugStopCodeCoverage)
+	vm.RegisterBuiltin("xdebug_get_code_coverage", builtinXdebugGetCodeCoverage)
+	vm.RegisterBuiltin("xdebug_code_coverage_started", builtinXdebugCodeCoverageStarted)
+	vm.RegisterBuiltin("phpdbg_start_oplog", builtinPhpdbgStartOplog)
+	vm.RegisterBuiltin("phpdbg_end_oplog", builtinPhpdbgEndOplog)
+	vm.RegisterBuiltin("phpdbg_get_executable", builtinPhpdbgGetExecutable)
+}
+
+// xdebug_start_code_coverage(int $options = 0): bool
+func builtinXdebugStartCodeCoverage(vm *VM, args []*types.Value) (*types.Value, error) {
+	options := 0
+	if len(args) > 0 {
+		options = int(args[0].Deref().ToInt())
+	}
+	vm.StartCoverage(options)
+	return types.NewBool(true), nil
+}
+
+// xdebug_stop_code_coverage(bool $cleanup = true): bool
+func builtinXdebugStopCodeCoverage(vm *VM, args []*types.Value) (*types.Value, error) {
+	if vm.coverage == nil || !vm.coverage.running {
+		return types.NewBool(false), nil
+	}
+	vm.StopCoverage(len(args) == 0 || args[0].Deref().ToBool())
+	return types.NewBool(true), nil
+}
+
+// xdebug_get_code_coverage(): array
+// Maps the files with lines executed to their lines: 1 for the lines
+// executed and, with XDEBUG_CC_UNUSED, -1 for the executable ones that
+// were not.
+func builtinXdebugGetCodeCoverage(vm *VM, args []*types.Value) (*types.Value, error) {
+	result := types.NewEmptyArray()
+	c := vm.coverage
+	if c == nil {
+		return types.NewArray(result), nil
+	}
+	for _, file := range c.Report() {
+		if len(c.hits[file.Path]) == 0 {
+			continue
+		}
+		lines := types.NewEmptyArray()
+		for _, line := range file.Lines {
+			switch {
+			case line.Hits > 0:
+				lines.Set(types.NewInt(int64(line.Line)), types.NewInt(1))
+			case c.options&CoverageUnused != 0:
+				lines.Set(types.NewInt(int64(line.Line)), types.NewInt(-1))
+			}
+		}
+		result.Set(types.NewString(file.Path), types.NewArray(lines))
+	}
+	return types.NewArray(result), nil
+}
+
+// xdebug_code_coverage_started(): bool
+func builtinXdebugCodeCoverageStarted(vm *VM, args []*types.Value) (*types.Value, error) {
+	return types.NewBool(vm.coverage != nil && vm.coverage.running), nil
+}
+
+// phpdbg_start_oplog(): void
+func builtinPhpdbgStartOplog(vm *VM, args []*types.Value) (*types.Value, error) {
+	vm.StartCoverage(0).Clear()
+	return types.NewNull(), nil
+}
+
+// phpdbg_end_oplog(array $options = []): ?array
+// Maps the files to the times their lines executed since
+// phpdbg_start_oplog(), and stops recording.
+func builtinPhpdbgEndOplog(vm *VM, args []*types.Value) (*types.Value, error) {
+	c := vm.coverage
+	if c == nil || !c.running {
+		return types.NewNull(), nil
+	}
+	vm.StopCoverage(true)
+
+	files := make([]string, 0, len(c.hits))
+	for file := range c.hits {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	result := types.NewEmptyArray()
+	for _, file := range files {
+		numbers := make([]int, 0, len(c.hits[file]))
+		for line := range c.hits[file] {
+			numbers = append(numbers, line)
+		}
+		sort.Ints(numbers)
+		lines := types.NewEmptyArray()
+		for _, line := range numbers {
+			lines.Set(types.NewInt(int64(line)), types.NewInt(int64(c.hits[file][line])))
+		}
+		result.Set(types.NewString(file), types.NewArray(lines))
+	}
+	return types.NewArray(result), nil
+}
+
+// phpdbg_get_executable(array $options = []): array
+// Maps the files loaded to their executable lines, each set to 0.
+func builtinPhpdbgGetExecutable(vm *VM, args []*types.Value) (*types.Value, error) {
+	executable := vm.ExecutableLines()
+	files := make([]string, 0, len(executable))
+	for file := range executable {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	result := types.NewEmptyArray()
+	for _, file := range files {
+		numbers := make([]int, 0, len(executable[file]))
+		for line := range executable[file] {
+			numbers = append(numbers, line)
+		}
+		sort.Ints(numbers)
+		lines := types.NewEmptyArray()
+		for _, line := range numbers {
+			lines.Set(types.NewInt(int64(line)), types.NewInt(0))
+		}
+		result.Set(types.NewString(file), types.NewArray(lines))
+	}
+	return types.NewArray(result), nil
+}
@@ -0,0 +1,168 @@
+package vm
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// coverageProgram runs main.php under code coverage: line 2 echoes, line 3
+// jumps over line 4 and line 5 calls double() of double.php. The unused()
+// it declares spans lines 20 and 21.
+func coverageProgram(t *testing.T, options int) *VM {
+	t.Helper()
+	vm := New()
+	double := doubleFunction("double")
+	double.File = "double.php"
+	double.Instructions[0].Lineno = 10
+	double.Instructions[1].Lineno = 11
+	vm.RegisterFunction("double", double)
+	unused := doubleFunction("unused")
+	unused.Instructions[0].Lineno = 20
+	unused.Instructions[1].Lineno = 21
+
+	vm.StartCoverage(options)
+	err := vm.ExecuteScript(&Script{
+		Path: "main.php",
+		Instructions: Instructions{
+			{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 0}, Lineno: 2},
+			{Opcode: OpJmp, Op1: Operand{Type: OpConst, Value: 3}, Lineno: 3},
+			{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 1}, Lineno: 4},
+			{Opcode: OpInitFcall, Op2: Operand{Type: OpConst, Value: 2}, Lineno: 5},
+			{Opcode: OpSendVal, Op1: Operand{Type: OpConst, Value: 3}, Lineno: 5},
+			{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 0}, Lineno: 5},
+			{Opcode: OpEcho, Op1: Operand{Type: OpTmpVar, Value: 0}, Lineno: 5},
+		},
+		Constants: []interface{}{"a", "b", "double", int64(4), &FunctionDecl{Function: unused}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm.GetOutput() != "a8" {
+		t.Fatalf("Expected output a8, got %q", vm.GetOutput())
+	}
+	return vm
+}
+
+func TestCoverage_Report(t *testing.T) {
+	vm := coverageProgram(t, 0)
+	expected := []FileCoverage{
+		{Path: "double.php", Lines: []LineCoverage{{10, 1}, {11, 1}}},
+		{Path: "main.php", Lines: []LineCoverage{{2, 1}, {3, 1}, {4, 0}, {5, 1}, {20, 0}, {21, 0}}},
+	}
+	report := vm.Coverage().Report()
+	if !reflect.DeepEqual(report, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, report)
+	}
+	if report[1].Covered() != 3 {
+		t.Errorf("Expected 3 lines of main.php covered, got %d", report[1].Covered())
+	}
+}
+
+func TestCoverage_CountsVisits(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"x"}
+	coverage := vm.StartCoverage(0)
+	err := vm.Execute(Instructions{
+		{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 0}, Lineno: 1},
+		{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 0}, Lineno: 1},
+		{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 0}, Lineno: 2},
+		{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 0}, Lineno: 1},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, hits := range coverage.Executed() {
+		if hits[1] != 2 || hits[2] != 1 {
+			t.Errorf("Expected line 1 entered twice and line 2 once, got %v", hits)
+		}
+	}
+}
+
+func TestCoverage_StartStop(t *testing.T) {
+	vm := coverageProgram(t, 0)
+	vm.StopCoverage(false)
+	if vm.Coverage() == nil || vm.Coverage().Running() {
+		t.Fatalf("Expected the stopped coverage to keep its lines")
+	}
+	vm.StopCoverage(true)
+	if vm.Coverage() != nil {
+		t.Errorf("Expected the cleanup to discard the coverage")
+	}
+}
+
+func TestCoverage_Lcov(t *testing.T) {
+	vm := coverageProgram(t, 0)
+	var out bytes.Buffer
+	if err := vm.Coverage().WriteLcov(&out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lcov := out.String()
+	for _, expected := range []string{"TN:\n", "/main.php\nDA:2,1\nDA:3,1\nDA:4,0\n", "LF:6\nLH:3\nend_of_record\n", "/double.php\nDA:10,1\nDA:11,1\nLF:2\nLH:2\n"} {
+		if !strings.Contains(lcov, expected) {
+			t.Errorf("Expected the tracefile to contain %q, got:\n%s", expected, lcov)
+		}
+	}
+}
+
+func TestCoverage_Clover(t *testing.T) {
+	vm := coverageProgram(t, 0)
+	var out bytes.Buffer
+	if err := vm.Coverage().WriteClover(&out, time.Unix(1700000000, 0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clover := out.String()
+	for _, expected := range []string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<coverage generated="1700000000">`,
+		`<line num="4" type="stmt" count="0"></line>`,
+		`<metrics files="2" loc="32" ncloc="32" statements="8" coveredstatements="5" elements="8" coveredelements="5"></metrics>`,
+	} {
+		if !strings.Contains(clover, expected) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", expected, clover)
+		}
+	}
+}
+
+func TestCoverage_XdebugFunctions(t *testing.T) {
+	vm := coverageProgram(t, CoverageUnused)
+	started, _ := builtinXdebugCodeCoverageStarted(vm, nil)
+	if !started.ToBool() {
+		t.Errorf("Expected xdebug_code_coverage_started() to be true")
+	}
+
+	result, err := builtinXdebugGetCodeCoverage(vm, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file, _ := result.ToArray().Get(types.NewString("main.php"))
+	main := file.ToArray()
+	for line, expected := range map[int64]int64{2: 1, 3: 1, 4: -1, 5: 1, 20: -1} {
+		if got, ok := main.Get(types.NewInt(line)); !ok || got.ToInt() != expected {
+			t.Errorf("Expected line %d to be %d, got %v", line, expected, got)
+		}
+	}
+
+	if stopped, _ := builtinXdebugStopCodeCoverage(vm, nil); !stopped.ToBool() || vm.Coverage() != nil {
+		t.Errorf("Expected xdebug_stop_code_coverage() to stop and clean up")
+	}
+}
+
+func TestCoverage_PhpdbgFunctions(t *testing.T) {
+	vm := coverageProgram(t, 0)
+	executable, _ := builtinPhpdbgGetExecutable(vm, nil)
+	if lines, _ := executable.ToArray().Get(types.NewString("main.php")); lines.ToArray().Len() != 6 {
+		t.Errorf("Expected 6 executable lines in main.php, got %d", lines.ToArray().Len())
+	}
+	oplog, _ := builtinPhpdbgEndOplog(vm, nil)
+	if lines, _ := oplog.ToArray().Get(types.NewString("double.php")); lines.ToArray().Len() != 2 {
+		t.Errorf("Expected 2 lines executed in double.php, got %d", lines.ToArray().Len())
+	}
+	if vm.Coverage() != nil {
+		t.Errorf("Expected phpdbg_end_oplog() to stop recording")
+	}
+}
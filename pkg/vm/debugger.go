@@ -0,0 +1,380 @@
+package vm
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Debugger
+// ============================================================================
+
+// DebugAction tells a Debugger how to resume after a stop
+type DebugAction int
+
+const (
+	DebugContinue DebugAction = iota // Run to the next breakpoint
+	DebugStepInto                    // Stop at the next line, entering calls ("step")
+	DebugStepOver                    // Stop at the next line of the frame or a caller ("next")
+	DebugStepOut                     // Stop once the frame has returned ("finish")
+	DebugDetach                      // Run to the end without the debugger
+)
+
+// Breakpoint stops the execution at a line of a file, or on entry to a
+// function when Function is set
+type Breakpoint struct {
+	ID       int
+	File     string // Path of the file, or its last components ("src/App.php")
+	Line     int
+	Function string // Function or Class::method name, case-insensitive
+	Hits     int    // Times the breakpoint stopped the execution
+}
+
+// DebugStop describes where the execution stopped
+type DebugStop struct {
+	File       string
+	Line       int
+	Function   string
+	Entry      bool        // Whether the line is the first one of its function
+	Step       bool        // Whether the step being run stops at the line
+	Breakpoint *Breakpoint // The breakpoint hit, nil after a step
+}
+
+// DebugClient drives a Debugger: Break is called whenever the execution
+// stops and returns how to resume. An error aborts the script with it.
+type DebugClient interface {
+	Break(d *Debugger, stop *DebugStop) (DebugAction, error)
+}
+
+// DebugFrame is a frame of the call stack as the debugger shows it
+type DebugFrame struct {
+	Function string
+	File     string
+	Line     int // Line of the instruction being executed
+	frame    *Frame
+}
+
+// DebugVariable is a variable of a frame with its value
+type DebugVariable struct {
+	Name  string
+	Value *types.Value
+}
+
+// Debugger stops the execution of a VM at breakpoints and steps, from the
+// line numbers of the instructions, and inspects the frames while it is
+// stopped. The VM consults it before each instruction (see dispatch).
+type Debugger struct {
+	vm          *VM
+	client      DebugClient
+	breakpoints []*Breakpoint
+	nextID      int
+	watches     []string
+
+	action    DebugAction // How the execution resumed
+	stepDepth int         // Frame depth the step started from
+
+	positions  []debugPosition // Last line executed at each frame depth
+	entry      *Frame          // Frame whose first instruction just ran
+	evaluating bool            // Whether Eval is running code, which does not stop
+}
+
+// debugPosition is the line a frame last executed
+type debugPosition struct {
+	frame *Frame
+	line  int
+}
+
+// NewDebugger attaches a debugger to a VM. It stops at the first line
+// executed, for the client to set breakpoints.
+func NewDebugger(vm *VM, client DebugClient) *Debugger {
+	d := &Debugger{vm: vm, client: client, action: DebugStepInto, nextID: 1}
+	vm.debugger = d
+	return d
+}
+
+// Detach stops debugging the VM
+func (d *Debugger) Detach() {
+	if d.vm.debugger == d {
+		d.vm.debugger = nil
+	}
+}
+
+// AddBreakpoint adds a breakpoint, numbering it
+func (d *Debugger) AddBreakpoint(bp *Breakpoint) *Breakpoint {
+	bp.ID = d.nextID
+	d.nextID++
+	d.breakpoints = append(d.breakpoints, bp)
+	return bp
+}
+
+// RemoveBreakpoint removes a breakpoint by number; false if there is none
+func (d *Debugger) RemoveBreakpoint(id int) bool {
+	for i, bp := range d.breakpoints {
+		if bp.ID == id {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Breakpoints returns the breakpoints in the order they were added
+func (d *Debugger) Breakpoints() []*Breakpoint {
+	return d.breakpoints
+}
+
+// AddWatch adds an expression the client evaluates at each stop
+func (d *Debugger) AddWatch(expr string) {
+	d.watches = append(d.watches, expr)
+}
+
+// RemoveWatch removes the watch expression at an index; false if there
+// is none
+func (d *Debugger) RemoveWatch(index int) bool {
+	if index < 0 || index >= len(d.watches) {
+		return false
+	}
+	d.watches = append(d.watches[:index], d.watches[index+1:]...)
+	return true
+}
+
+// Watches returns the watch expressions
+func (d *Debugger) Watches() []string {
+	return d.watches
+}
+
+// check runs before each instruction: when it starts a new line that a
+// breakpoint or the current step stops at, the client takes over
+func (d *Debugger) check(frame *Frame, instr Instruction) error {
+	if frame.ip == 1 {
+		d.entry = frame
+	}
+	if d.evaluating || instr.Lineno == 0 {
+		return nil
+	}
+	entered := d.entry == frame
+	d.entry = nil
+
+	// Frames deeper than this one have returned: a step that started in one
+	// of them stops back in the caller even on the line of the call
+	depth := d.vm.frameIndex
+	returned := len(d.positions) > depth+1
+	if returned {
+		d.positions = d.positions[:depth+1]
+	}
+	for len(d.positions) <= depth {
+		d.positions = append(d.positions, debugPosition{})
+	}
+	line := int(instr.Lineno)
+	previous := d.positions[depth]
+	d.positions[depth] = debugPosition{frame: frame, line: line}
+	newLine := entered || previous.frame != frame || previous.line != line
+	if !newLine && (!returned || depth >= d.stepDepth) {
+		return nil
+	}
+
+	stop := &DebugStop{
+		File:     d.vm.frameFile(frame),
+		Line:     line,
+		Function: frameFunction(frame),
+		Entry:    entered,
+		Step:     d.stepDone(depth),
+	}
+	for _, bp := range d.breakpoints {
+		if newLine && bp.Matches(stop) {
+			bp.Hits++
+			stop.Breakpoint = bp
+			break
+		}
+	}
+	if stop.Breakpoint == nil && !stop.Step {
+		return nil
+	}
+
+	action, err := d.client.Break(d, stop)
+	if err != nil {
+		return err
+	}
+	d.action, d.stepDepth = action, depth
+	if action == DebugDetach {
+		d.Detach()
+	}
+	return nil
+}
+
+// stepDone reports whether the current step stops at a new line of the
+// frame at depth
+func (d *Debugger) stepDone(depth int) bool {
+	switch d.action {
+	case DebugStepInto:
+		return true
+	case DebugStepOver:
+		return depth <= d.stepDepth
+	case DebugStepOut:
+		return depth < d.stepDepth
+	}
+	return false
+}
+
+// Matches reports whether a breakpoint stops at a line
+func (bp *Breakpoint) Matches(stop *DebugStop) bool {
+	if bp.Function != "" {
+		return stop.Entry && strings.EqualFold(strings.TrimPrefix(bp.Function, "\\"), stop.Function)
+	}
+	return bp.Line == stop.Line && sameFile(stop.File, bp.File)
+}
+
+// sameFile reports whether a file path is the one a breakpoint names: the
+// same file, or one whose path ends with the components given
+func sameFile(path, name string) bool {
+	if name == "" || path == "" {
+		return false
+	}
+	path, name = filepath.ToSlash(filepath.Clean(path)), filepath.ToSlash(filepath.Clean(name))
+	if path == name || strings.HasSuffix(path, "/"+name) {
+		return true
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absName, err := filepath.Abs(name)
+	return err == nil && absPath == absName
+}
+
+// frameFile returns the file a frame executes, the script path for the
+// code not compiled from a file
+func (vm *VM) frameFile(frame *Frame) string {
+	if frame.fn.File != "" {
+		return frame.fn.File
+	}
+	return vm.scriptPath
+}
+
+// frameFunction returns the name of the function a frame executes, with
+// its class for methods
+func frameFunction(frame *Frame) string {
+	name := frame.fn.Name
+	if frame.currentClass != nil && !strings.Contains(name, "::") {
+		return frame.currentClass.Name + "::" + name
+	}
+	return name
+}
+
+// ============================================================================
+// Inspection
+// ============================================================================
+
+// Backtrace returns the frames of the call stack, innermost first
+func (d *Debugger) Backtrace() []DebugFrame {
+	frames := make([]DebugFrame, 0, d.vm.frameIndex+1)
+	for i := d.vm.frameIndex; i >= 0; i-- {
+		frame := d.vm.frames[i]
+		line := 0
+		if frame.ip > 0 && frame.ip <= len(frame.fn.Instructions) {
+			line = int(frame.fn.Instructions[frame.ip-1].Lineno)
+		}
+		frames = append(frames, DebugFrame{
+			Function: frameFunction(frame),
+			File:     d.vm.frameFile(frame),
+			Line:     line,
+			frame:    frame,
+		})
+	}
+	return frames
+}
+
+// frameAt returns the frame at a depth of the backtrace, 0 being the
+// innermost
+func (d *Debugger) frameAt(depth int) (*Frame, error) {
+	if depth < 0 || depth > d.vm.frameIndex {
+		return nil, fmt.Errorf("no frame at depth %d", depth)
+	}
+	return d.vm.frames[d.vm.frameIndex-depth], nil
+}
+
+// Locals returns the variables of the frame at a depth of the backtrace
+// that are set, in the order the function declares them
+func (d *Debugger) Locals(depth int) ([]DebugVariable, error) {
+	frame, err := d.frameAt(depth)
+	if err != nil {
+		return nil, err
+	}
+	var vars []DebugVariable
+	for i, name := range frame.fn.Variables {
+		if i >= len(frame.locals) || frame.locals[i] == nil {
+			continue
+		}
+		if value := frame.locals[i].Deref(); !value.IsUndef() {
+			vars = append(vars, DebugVariable{Name: name, Value: value})
+		}
+	}
+	for name, value := range frame.namedVars {
+		if value := value.Deref(); !value.IsUndef() {
+			vars = append(vars, DebugVariable{Name: name, Value: value})
+		}
+	}
+	return vars, nil
+}
+
+// This returns the $this of the frame at a depth of the backtrace, nil
+// outside objects
+func (d *Debugger) This(depth int) (*types.Object, error) {
+	frame, err := d.frameAt(depth)
+	if err != nil {
+		return nil, err
+	}
+	return frame.thisObject, nil
+}
+
+// Stack returns the temporaries of the frame at a depth of the backtrace
+// holding a value: the operands and results of its instructions, named
+// T0, T1...
+func (d *Debugger) Stack(depth int) ([]DebugVariable, error) {
+	frame, err := d.frameAt(depth)
+	if err != nil {
+		return nil, err
+	}
+	var temps []DebugVariable
+	base := frame.fn.tempSlot(0)
+	for slot := base; slot < len(frame.locals); slot++ {
+		if value := frame.locals[slot]; value != nil {
+			temps = append(temps, DebugVariable{Name: fmt.Sprintf("T%d", slot-base), Value: value.Deref()})
+		}
+	}
+	return temps, nil
+}
+
+// Eval evaluates a PHP expression in the scope of the frame at a depth of
+// the backtrace, like eval('return expr;') would there. The code runs
+// without stopping; the exceptions it throws are returned as errors.
+func (d *Debugger) Eval(expr string, depth int) (*types.Value, error) {
+	frame, err := d.frameAt(depth)
+	if err != nil {
+		return nil, err
+	}
+	if d.vm.compileScript == nil {
+		return nil, fmt.Errorf("cannot evaluate code: no script compiler configured")
+	}
+
+	line := 0
+	if frame.ip > 0 && frame.ip <= len(frame.fn.Instructions) {
+		line = int(frame.fn.Instructions[frame.ip-1].Lineno)
+	}
+	path := fmt.Sprintf("%s(%d) : eval()'d code", d.vm.frameFile(frame), line)
+	script, err := d.vm.compileScript(path, []byte("<?php return ("+expr+");"))
+	if err != nil {
+		return nil, err
+	}
+
+	d.evaluating = true
+	defer func() { d.evaluating = false }()
+	result, err := d.vm.executeIncluded(frame, scriptFunction(script), d.vm.frameFile(frame))
+	if thrown, ok := err.(*ThrowableError); ok {
+		return nil, fmt.Errorf("Uncaught %s: %s", thrown.Object.ClassName, throwableProperty(thrown.Object, "message").ToString())
+	}
+	return result, err
+}
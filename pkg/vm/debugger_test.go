@@ -0,0 +1,159 @@
+package vm
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// scriptedClient records where the debugger stops and resumes with the
+// actions given, continuing once they run out
+type scriptedClient struct {
+	actions []DebugAction
+	stops   []string
+	onBreak func(d *Debugger, stop *DebugStop)
+}
+
+func (c *scriptedClient) Break(d *Debugger, stop *DebugStop) (DebugAction, error) {
+	c.stops = append(c.stops, fmt.Sprintf("%s:%d", stop.Function, stop.Line))
+	if c.onBreak != nil {
+		c.onBreak(d, stop)
+	}
+	if len(c.actions) == 0 {
+		return DebugContinue, nil
+	}
+	action := c.actions[0]
+	c.actions = c.actions[1:]
+	return action, nil
+}
+
+// debugProgram runs echo double(4); on line 2 of main.php and echo "done";
+// on line 3, with double() on lines 10 and 11, under a debugger
+func debugProgram(t *testing.T, client *scriptedClient, breakpoints ...*Breakpoint) *VM {
+	t.Helper()
+	vm := New()
+	vm.SetScriptPath("main.php")
+	vm.constants = []interface{}{"double", int64(4), "done"}
+	double := doubleFunction("double")
+	double.Variables = []string{"x"}
+	double.File = "/src/lib/double.php"
+	double.Instructions[0].Lineno = 10
+	double.Instructions[1].Lineno = 11
+	vm.RegisterFunction("double", double)
+
+	d := NewDebugger(vm, client)
+	for _, bp := range breakpoints {
+		d.AddBreakpoint(bp)
+	}
+	err := vm.Execute(Instructions{
+		{Opcode: OpInitFcall, Op2: Operand{Type: OpConst, Value: 0}, Lineno: 2},
+		{Opcode: OpSendVal, Op1: Operand{Type: OpConst, Value: 1}, Lineno: 2},
+		{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 0}, Lineno: 2},
+		{Opcode: OpEcho, Op1: Operand{Type: OpTmpVar, Value: 0}, Lineno: 2},
+		{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 2}, Lineno: 3},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm.GetOutput() != "8done" {
+		t.Errorf("Expected output 8done, got %q", vm.GetOutput())
+	}
+	return vm
+}
+
+func TestDebugger_Stepping(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []DebugAction
+		stops   []string
+	}{
+		{"step into", []DebugAction{DebugStepInto, DebugStepInto, DebugStepInto, DebugStepInto}, []string{"main:2", "double:10", "double:11", "main:2", "main:3"}},
+		{"step over", []DebugAction{DebugStepOver}, []string{"main:2", "main:3"}},
+		{"step out", []DebugAction{DebugStepInto, DebugStepOut}, []string{"main:2", "double:10", "main:2"}},
+		{"step over a return", []DebugAction{DebugStepInto, DebugStepOver, DebugStepOver, DebugStepOver}, []string{"main:2", "double:10", "double:11", "main:2", "main:3"}},
+		{"continue", []DebugAction{DebugContinue}, []string{"main:2"}},
+		{"detach", []DebugAction{DebugDetach}, []string{"main:2"}},
+	}
+	for _, tt := range tests {
+		client := &scriptedClient{actions: tt.actions}
+		debugProgram(t, client)
+		if !reflect.DeepEqual(client.stops, tt.stops) {
+			t.Errorf("%s: stopped at %v, want %v", tt.name, client.stops, tt.stops)
+		}
+	}
+}
+
+func TestDebugger_Breakpoints(t *testing.T) {
+	tests := []struct {
+		name       string
+		breakpoint *Breakpoint
+		stops      []string
+	}{
+		{"line", &Breakpoint{File: "main.php", Line: 3}, []string{"main:2", "main:3"}},
+		{"path suffix", &Breakpoint{File: "lib/double.php", Line: 11}, []string{"main:2", "double:11"}},
+		{"function", &Breakpoint{Function: "\\DOUBLE"}, []string{"main:2", "double:10"}},
+		{"other file", &Breakpoint{File: "other.php", Line: 3}, []string{"main:2"}},
+	}
+	for _, tt := range tests {
+		client := &scriptedClient{}
+		debugProgram(t, client, tt.breakpoint)
+		if !reflect.DeepEqual(client.stops, tt.stops) {
+			t.Errorf("%s: stopped at %v, want %v", tt.name, client.stops, tt.stops)
+		}
+		if hits := len(tt.stops) - 1; tt.breakpoint.Hits != hits {
+			t.Errorf("%s: expected %d hits, got %d", tt.name, hits, tt.breakpoint.Hits)
+		}
+	}
+}
+
+func TestDebugger_Inspection(t *testing.T) {
+	var locals, temps []DebugVariable
+	var backtrace []DebugFrame
+	client := &scriptedClient{onBreak: func(d *Debugger, stop *DebugStop) {
+		if stop.Line != 11 {
+			return
+		}
+		backtrace = d.Backtrace()
+		locals, _ = d.Locals(0)
+		temps, _ = d.Stack(0)
+		if _, err := d.Locals(2); err == nil {
+			t.Error("Expected an error for a frame beyond the stack")
+		}
+	}}
+	debugProgram(t, client, &Breakpoint{File: "double.php", Line: 11})
+
+	if len(backtrace) != 2 || backtrace[0].Function != "double" || backtrace[0].File != "/src/lib/double.php" || backtrace[1].Function != "main" || backtrace[1].Line != 2 {
+		t.Errorf("Unexpected backtrace %+v", backtrace)
+	}
+	if len(locals) != 1 || locals[0].Name != "x" || !locals[0].Value.Identical(types.NewInt(4)) {
+		t.Errorf("Expected $x = 4, got %+v", locals)
+	}
+	if len(temps) != 1 || temps[0].Name != "T0" || !temps[0].Value.Identical(types.NewInt(8)) {
+		t.Errorf("Expected T0 = 8, got %+v", temps)
+	}
+}
+
+func TestDebugger_ManageBreakpointsAndWatches(t *testing.T) {
+	d := NewDebugger(New(), &scriptedClient{})
+	first := d.AddBreakpoint(&Breakpoint{File: "a.php", Line: 1})
+	second := d.AddBreakpoint(&Breakpoint{Function: "f"})
+	if first.ID != 1 || second.ID != 2 {
+		t.Errorf("Expected breakpoints 1 and 2, got %d and %d", first.ID, second.ID)
+	}
+	if !d.RemoveBreakpoint(1) || d.RemoveBreakpoint(1) || len(d.Breakpoints()) != 1 {
+		t.Errorf("Expected breakpoint 1 to be removed once, got %+v", d.Breakpoints())
+	}
+
+	d.AddWatch("$a")
+	d.AddWatch("$b + 1")
+	if !d.RemoveWatch(0) || d.RemoveWatch(5) || !reflect.DeepEqual(d.Watches(), []string{"$b + 1"}) {
+		t.Errorf("Unexpected watches %v", d.Watches())
+	}
+
+	d.Detach()
+	if d.vm.debugger != nil {
+		t.Error("Expected the debugger to be detached")
+	}
+}
@@ -0,0 +1,110 @@
+package vm
+
+import (
+	"fmt"
+
+	varfuncs "github.com/krizos/php-go/pkg/stdlib/var"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Object Debug View
+// ============================================================================
+
+// DebugProperties returns the properties shown when inspecting an object.
+// If the class defines __debugInfo(), its returned array replaces the raw
+// properties. This is the single source for var_dump, print_r and the
+// debugger's variable views, so all of them agree.
+func (vm *VM) DebugProperties(obj *types.Object) ([]types.DebugProperty, error) {
+	if obj.ClassEntry == nil {
+		return obj.DebugProperties(), nil
+	}
+
+	method, ok := obj.ClassEntry.GetMethod("__debugInfo")
+	if !ok {
+		if method = obj.ClassEntry.GetMagicMethod("__debugInfo"); method == nil {
+			return obj.DebugProperties(), nil
+		}
+	}
+
+	result, err := vm.invokeTarget(vm.methodTarget(obj.ClassEntry, obj, method), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result = result.Deref()
+	switch result.Type() {
+	case types.TypeArray:
+		return types.DebugPropertiesFromArray(result.ToArray()), nil
+	case types.TypeNull:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%s::__debugInfo() must return an array", obj.ClassName)
+	}
+}
+
+// Dumper returns a var_dump/print_r/var_export renderer that honours
+// __debugInfo and reports var_export warnings as PHP warnings
+func (vm *VM) Dumper() *varfuncs.Dumper {
+	dumper := varfuncs.NewDumper(vm.DebugProperties)
+	dumper.Warning = func(message string) {
+		vm.warning("%s", message)
+	}
+	return dumper
+}
+
+// ============================================================================
+// Dump Builtins
+// ============================================================================
+
+// registerDumpBuiltins registers the variable dumping builtins
+func (vm *VM) registerDumpBuiltins() {
+	vm.RegisterBuiltin("var_dump", builtinVarDump)
+	vm.RegisterBuiltin("print_r", builtinPrintR)
+	vm.RegisterBuiltin("var_export", builtinVarExport)
+}
+
+// var_dump(mixed $value, mixed ...$values): void
+func builtinVarDump(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("var_dump() expects at least 1 argument, 0 given")
+	}
+	dumper := vm.Dumper()
+	for _, arg := range args {
+		output, err := dumper.VarDump(arg)
+		if err != nil {
+			return nil, err
+		}
+		vm.writeOutput([]byte(output))
+	}
+	return types.NewNull(), nil
+}
+
+// print_r(mixed $value, bool $return = false): string|bool
+func builtinPrintR(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("print_r() expects at least 1 argument, 0 given")
+	}
+	output, err := vm.Dumper().PrintR(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 1 && args[1].ToBool() {
+		return types.NewString(output), nil
+	}
+	vm.writeOutput([]byte(output))
+	return types.NewBool(true), nil
+}
+
+// var_export(mixed $value, bool $return = false): ?string
+func builtinVarExport(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("var_export() expects at least 1 argument, 0 given")
+	}
+	output := vm.Dumper().VarExport(args[0])
+	if len(args) > 1 && args[1].ToBool() {
+		return types.NewString(output), nil
+	}
+	vm.writeOutput([]byte(output))
+	return types.NewNull(), nil
+}
@@ -0,0 +1,119 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// newDebugTestClass creates a class with one property of each visibility
+func newDebugTestClass() *types.ClassEntry {
+	class := types.NewClassEntry("Point")
+	class.Properties["x"] = &types.PropertyDef{Name: "x", Visibility: types.VisibilityPublic, Default: types.NewInt(1), DeclaringClass: "Point"}
+	class.Properties["y"] = &types.PropertyDef{Name: "y", Visibility: types.VisibilityProtected, Default: types.NewInt(2), DeclaringClass: "Point"}
+	class.Properties["z"] = &types.PropertyDef{Name: "z", Visibility: types.VisibilityPrivate, Default: types.NewInt(3), DeclaringClass: "Point"}
+	return class
+}
+
+func TestDebugProperties_Raw(t *testing.T) {
+	vm := New()
+	obj := types.NewObjectFromClass(newDebugTestClass())
+
+	props, err := vm.DebugProperties(obj)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(props) != 3 {
+		t.Fatalf("Expected 3 properties, got %d", len(props))
+	}
+	if props[2].Key.ToString() != "z" || props[2].Visibility != types.VisibilityPrivate || props[2].Class != "Point" {
+		t.Errorf("Unexpected private property entry: %+v", props[2])
+	}
+}
+
+func TestDebugProperties_DebugInfo(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"answer", int64(42)}
+
+	// public function __debugInfo() { return ['answer' => 42]; }
+	class := newDebugTestClass()
+	class.Methods["__debugInfo"] = &types.MethodDef{
+		Name:       "__debugInfo",
+		Visibility: types.VisibilityPublic,
+		IsMagic:    true,
+		Instructions: []interface{}{
+			Instruction{Opcode: OpInitArray, Result: Operand{Type: OpTmpVar, Value: 0}},
+			Instruction{Opcode: OpAddArrayElement, Op1: Operand{Type: OpConst, Value: 1}, Op2: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 0}},
+			Instruction{Opcode: OpReturn, Op1: Operand{Type: OpTmpVar, Value: 0}},
+		},
+		NumLocals: 10,
+	}
+	vm.classes["Point"] = class
+	obj := types.NewObjectFromClass(class)
+
+	props, err := vm.DebugProperties(obj)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(props) != 1 || props[0].Key.ToString() != "answer" || props[0].Value.ToInt() != 42 {
+		t.Fatalf("Expected __debugInfo result, got %+v", props)
+	}
+
+	// var_dump and print_r go through the same path
+	if _, err := builtinVarDump(vm, []*types.Value{types.NewObject(obj)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "object(Point)#" + types.NewInt(int64(obj.ObjectID)).ToString() + " (1) {\n  [\"answer\"]=>\n  int(42)\n}\n"
+	if vm.GetOutput() != expected {
+		t.Errorf("Expected var_dump output:\n%s\ngot:\n%s", expected, vm.GetOutput())
+	}
+
+	result, err := builtinPrintR(vm, []*types.Value{types.NewObject(obj), types.NewBool(true)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result.ToString(), "[answer] => 42") || strings.Contains(result.ToString(), "[x]") {
+		t.Errorf("print_r should render __debugInfo result, got:\n%s", result.ToString())
+	}
+}
+
+func TestVarDump_VisibilityAnnotations(t *testing.T) {
+	vm := New()
+	obj := types.NewObjectFromClass(newDebugTestClass())
+
+	if _, err := builtinVarDump(vm, []*types.Value{types.NewObject(obj)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := vm.GetOutput()
+	for _, key := range []string{`["x"]=>`, `["y":protected]=>`, `["z":"Point":private]=>`} {
+		if !strings.Contains(output, key) {
+			t.Errorf("Expected %s in output:\n%s", key, output)
+		}
+	}
+}
+
+func TestVarExport_Builtin(t *testing.T) {
+	vm := New()
+	arr := types.NewEmptyArray()
+	arr.Set(types.NewString("a"), types.NewInt(1))
+
+	result, err := builtinVarExport(vm, []*types.Value{types.NewArray(arr), types.NewBool(true)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ToString() != "array (\n  'a' => 1,\n)" || vm.GetOutput() != "" {
+		t.Errorf("Expected returned export, got %q (output %q)", result.ToString(), vm.GetOutput())
+	}
+
+	// Without $return the export goes to the output buffer, and ignores __debugInfo
+	obj := types.NewObjectFromClass(newDebugTestClass())
+	if _, err := builtinVarExport(vm, []*types.Value{types.NewObject(obj)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "\\Point::__set_state(array(\n   'x' => 1,\n   'y' => 2,\n   'z' => 3,\n))"
+	if vm.GetOutput() != expected {
+		t.Errorf("Expected var_export output:\n%s\ngot:\n%s", expected, vm.GetOutput())
+	}
+}
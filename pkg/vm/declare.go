@@ -0,0 +1,265 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Class Declarations
+// ============================================================================
+
+// ClassDecl describes the class, interface or trait a DECLARE_CLASS
+// instruction declares. The compiler fills Class with the members declared
+// in the body, including the insteadof and as rules of its trait uses; the
+// classes it names are resolved when the declaration runs.
+type ClassDecl struct {
+	Class      *types.ClassEntry
+	Parent     string
+	Interfaces []string
+	Traits     []string
+}
+
+// opDeclareClass declares a class: its traits are applied first, then it
+// inherits from its parent, links its interfaces and is checked for
+// unimplemented abstract methods before it is registered
+// Op1: the ClassDecl constant
+func (vm *VM) opDeclareClass(frame *Frame, instr Instruction) error {
+	constant, ok := vm.constantOperand(frame, instr.Op1)
+	if !ok {
+		return fmt.Errorf("invalid class declaration operand")
+	}
+	decl, ok := constant.(*ClassDecl)
+	if !ok {
+		return fmt.Errorf("constant %d is not a class declaration", instr.Op1.Value)
+	}
+
+	class, err := vm.linkClass(decl)
+	if err != nil {
+		return err
+	}
+	return vm.DeclareClass(class)
+}
+
+// LinkClass declares the class of a declaration made outside compiled
+// code, such as by a Go extension: its parent, interfaces and traits are
+// resolved as a DECLARE_CLASS instruction resolves them
+func (vm *VM) LinkClass(decl *ClassDecl) error {
+	class, err := vm.linkClass(decl)
+	if err != nil {
+		return err
+	}
+	return vm.DeclareClass(class)
+}
+
+// linkClass builds the class entry of a declaration
+func (vm *VM) linkClass(decl *ClassDecl) (*types.ClassEntry, error) {
+	name := decl.Class.Name
+	if _, exists := vm.lookupClass(name); exists {
+		return nil, fmt.Errorf("Cannot declare %s %s, because the name is already in use", classKind(decl.Class), name)
+	}
+
+	class := copyClassEntry(decl.Class)
+
+	// Trait methods override inherited ones, while abstract trait methods
+	// may be implemented by the parent class
+	var parent *types.ClassEntry
+	if decl.Parent != "" {
+		var err error
+		if parent, err = vm.linkedClass(decl.Parent); err != nil {
+			return nil, err
+		}
+		switch {
+		case parent.IsInterface:
+			return nil, fmt.Errorf("Class %s cannot extend interface %s", name, parent.Name)
+		case parent.IsTrait:
+			return nil, fmt.Errorf("Class %s cannot extend trait %s", name, parent.Name)
+		}
+		class.ParentClass = parent
+	}
+
+	for _, traitName := range decl.Traits {
+		trait, err := vm.linkedClass(traitName)
+		if err != nil {
+			return nil, err
+		}
+		if !trait.IsTrait {
+			return nil, fmt.Errorf("%s cannot use %s - it is not a trait", name, trait.Name)
+		}
+		class.Traits = append(class.Traits, traitEntry(trait))
+	}
+	if err := class.ApplyTraits(); err != nil {
+		return nil, err
+	}
+	// Each class using a trait has its own static properties
+	for _, trait := range class.Traits {
+		for propName, prop := range trait.Properties {
+			if _, exists := class.StaticProperties[propName]; exists || !prop.IsStatic {
+				continue
+			}
+			class.StaticProperties[propName] = types.NewNull()
+			if prop.Default != nil {
+				class.StaticProperties[propName] = prop.Default.Copy()
+			}
+		}
+	}
+	if err := class.InheritFrom(parent); err != nil {
+		return nil, err
+	}
+
+	for _, ifaceName := range decl.Interfaces {
+		iface, err := vm.linkedClass(ifaceName)
+		if err != nil {
+			return nil, err
+		}
+		if !iface.IsInterface {
+			return nil, fmt.Errorf("%s cannot implement %s - it is not an interface", name, iface.Name)
+		}
+		class.Interfaces = append(class.Interfaces, classInterface(iface))
+	}
+
+	// A class without a constructor of its own uses its parent's
+	if method, ok := class.Methods["__construct"]; ok {
+		class.Constructor = method
+	} else if parent != nil {
+		class.Constructor = parent.Constructor
+	}
+	if method, ok := class.Methods["__destruct"]; ok {
+		class.Destructor = method
+	}
+	for methodName, method := range class.Methods {
+		if method.IsMagic {
+			class.MagicMethods[methodName] = method
+		}
+	}
+	return class, nil
+}
+
+// linkedClass loads a class a declaration names
+func (vm *VM) linkedClass(name string) (*types.ClassEntry, error) {
+	class, exists, err := vm.loadClass(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, vm.ThrowError("Error", "Class \"%s\" not found", name)
+	}
+	return class, nil
+}
+
+// classKind names the kind of a class entry for error messages
+func classKind(class *types.ClassEntry) string {
+	switch {
+	case class.IsInterface:
+		return "interface"
+	case class.IsTrait:
+		return "trait"
+	case class.IsEnum:
+		return "enum"
+	}
+	return "class"
+}
+
+// copyClassEntry copies the members of a declared class, so the
+// declaration can be linked again by another VM
+func copyClassEntry(template *types.ClassEntry) *types.ClassEntry {
+	class := *template
+	class.Constants = make(map[string]*types.ClassConstant, len(template.Constants))
+	for name, constant := range template.Constants {
+		// Initializers evaluated on declaration set the value
+		copied := *constant
+		class.Constants[name] = &copied
+	}
+	class.Properties = make(map[string]*types.PropertyDef, len(template.Properties))
+	for name, prop := range template.Properties {
+		copied := *prop
+		class.Properties[name] = &copied
+	}
+	class.StaticProperties = make(map[string]*types.Value, len(template.StaticProperties))
+	for name, value := range template.StaticProperties {
+		class.StaticProperties[name] = value.Copy()
+	}
+	class.DefaultProperties = make(map[string]*types.Value, len(template.DefaultProperties))
+	for name, value := range template.DefaultProperties {
+		class.DefaultProperties[name] = value
+	}
+	class.Methods = make(map[string]*types.MethodDef, len(template.Methods))
+	for name, method := range template.Methods {
+		copied := *method
+		class.Methods[name] = &copied
+	}
+	class.MagicMethods = make(map[string]*types.MethodDef)
+	class.Traits = nil
+	class.Interfaces = nil
+	return &class
+}
+
+// traitEntry returns the trait view of a declared trait
+func traitEntry(class *types.ClassEntry) *types.TraitEntry {
+	trait := types.NewTraitEntry(class.Name)
+	trait.Properties = class.Properties
+	trait.Methods = class.Methods
+	trait.Constants = class.Constants
+	return trait
+}
+
+// classInterface returns the interface view of a declared interface
+func classInterface(class *types.ClassEntry) *types.InterfaceEntry {
+	iface := types.NewInterfaceEntry(class.Name)
+	iface.Methods = class.Methods
+	iface.Constants = class.Constants
+	iface.ParentInterfaces = class.Interfaces
+	return iface
+}
+
+// interfaceClass returns the class entry registering a built-in interface
+func interfaceClass(iface *types.InterfaceEntry) *types.ClassEntry {
+	class := types.NewClassEntry(iface.Name)
+	class.IsInterface = true
+	class.Methods = iface.Methods
+	class.Constants = iface.Constants
+	class.Interfaces = iface.ParentInterfaces
+	return class
+}
+
+// ============================================================================
+// Function Declarations
+// ============================================================================
+
+// FunctionDecl describes the function a DECLARE_FUNCTION instruction
+// declares, or the closure a DECLARE_LAMBDA_FUNCTION creates. The function
+// holds its own op array: instructions, constants and locals.
+type FunctionDecl struct {
+	Function *CompiledFunction
+}
+
+// functionDecl fetches the function declaration an operand references
+func (vm *VM) functionDecl(frame *Frame, op Operand) (*FunctionDecl, error) {
+	constant, ok := vm.constantOperand(frame, op)
+	if !ok {
+		return nil, fmt.Errorf("invalid function declaration operand")
+	}
+	decl, ok := constant.(*FunctionDecl)
+	if !ok || decl.Function == nil {
+		return nil, fmt.Errorf("constant %d is not a function declaration", op.Value)
+	}
+	return decl, nil
+}
+
+// opDeclareFunction registers a declared function
+// Op1: the FunctionDecl constant
+func (vm *VM) opDeclareFunction(frame *Frame, instr Instruction) error {
+	decl, err := vm.functionDecl(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+
+	name := decl.Function.Name
+	if _, exists := vm.lookupFunction(name); exists {
+		return fmt.Errorf("Cannot redeclare %s()", name)
+	}
+	fn := *decl.Function
+	vm.RegisterFunction(name, &fn)
+	return nil
+}
@@ -0,0 +1,134 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// declareClass dispatches DECLARE_CLASS for a declaration
+func declareClass(vm *VM, decl *ClassDecl) error {
+	vm.constants = append(vm.constants, decl)
+	frame := NewFrame(mainScript(nil))
+	instr := Instruction{Opcode: OpDeclareClass, Op1: Operand{Type: OpConst, Value: uint32(len(vm.constants) - 1)}}
+	return vm.dispatch(frame, instr)
+}
+
+// newClassDecl returns a declaration of an empty class
+func newClassDecl(name string) *ClassDecl {
+	return &ClassDecl{Class: types.NewClassEntry(name)}
+}
+
+// declMethod adds a method declared by a declaration
+func declMethod(decl *ClassDecl, name string, method types.MethodDef) {
+	method.Name = name
+	method.DeclaringClass = decl.Class.Name
+	decl.Class.Methods[name] = &method
+}
+
+func TestDeclareClass_Traits(t *testing.T) {
+	vm := New()
+	first := []interface{}{Instruction{Opcode: OpReturn, Op1: Operand{Type: OpConst, Value: 0}}}
+	second := []interface{}{Instruction{Opcode: OpReturn, Op1: Operand{Type: OpConst, Value: 1}}}
+
+	// trait Hello { static $count = 0; function hi() {...} abstract function name(); static function make() {...} }
+	hello := newClassDecl("Hello")
+	hello.Class.IsTrait = true
+	hello.Class.Properties["count"] = &types.PropertyDef{Name: "count", IsStatic: true, HasDefault: true, Default: types.NewInt(0)}
+	declMethod(hello, "hi", types.MethodDef{Instructions: first, NumLocals: 1})
+	declMethod(hello, "name", types.MethodDef{IsAbstract: true})
+	declMethod(hello, "make", types.MethodDef{IsStatic: true, Instructions: second, NumLocals: 1})
+
+	// trait World { function hi() {...} }
+	world := newClassDecl("World")
+	world.Class.IsTrait = true
+	declMethod(world, "hi", types.MethodDef{Instructions: second})
+
+	// class Named { function name() {...} }
+	named := newClassDecl("Named")
+	declMethod(named, "name", types.MethodDef{})
+
+	for _, decl := range []*ClassDecl{hello, world, named} {
+		if err := declareClass(vm, decl); err != nil {
+			t.Fatalf("unexpected error declaring %s: %v", decl.Class.Name, err)
+		}
+	}
+	if method := vm.classes["Hello"].Methods["hi"]; len(method.Instructions) != 1 || method.NumLocals != 1 {
+		t.Errorf("Expected hi() to be linked to its body, got %d instructions", len(method.Instructions))
+	}
+
+	// class Greeter extends Named { use Hello, World { Hello::hi insteadof World; World::hi as protected worldHi; make as private; } }
+	greeter := newClassDecl("Greeter")
+	greeter.Parent = "Named"
+	greeter.Traits = []string{"Hello", "World"}
+	greeter.Class.TraitPrecedence["hi"] = "Hello"
+	greeter.Class.TraitAliases["worldHi"] = "World::hi:protected"
+	greeter.Class.TraitAliases["make"] = "::make:private"
+	if err := declareClass(vm, greeter); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	class := vm.classes["Greeter"]
+	if hi := class.Methods["hi"]; hi == nil || hi.Instructions[0].(Instruction).Op1.Value != 0 {
+		t.Error("Expected hi() from Hello")
+	}
+	if worldHi := class.Methods["worldHi"]; worldHi == nil || worldHi.Visibility != types.VisibilityProtected {
+		t.Error("Expected protected alias worldHi() of World::hi()")
+	}
+	if factory := class.Methods["make"]; factory == nil || !factory.IsStatic || factory.Visibility != types.VisibilityPrivate {
+		t.Error("Expected private static make()")
+	}
+	if name := class.Methods["name"]; name == nil || name.IsAbstract {
+		t.Error("Expected the abstract trait method name() to be implemented by the parent")
+	}
+	if count, ok := class.GetStaticProperty("count"); !ok || count.ToInt() != 0 {
+		t.Error("Expected Greeter to have its own static $count")
+	}
+
+	// Trait methods run in the scope of the using class
+	if scope := vm.methodScope(class, class.Methods["hi"]); scope != class {
+		t.Errorf("Expected hi() to run in the scope of Greeter, got %s", scope.Name)
+	}
+}
+
+func TestDeclareClass_Errors(t *testing.T) {
+	vm := New()
+	iface := newClassDecl("Countable2")
+	iface.Class.IsInterface = true
+	declMethod(iface, "count", types.MethodDef{IsAbstract: true})
+	if err := declareClass(vm, iface); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := declareClass(vm, newClassDecl("Plain")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		setup func(decl *ClassDecl)
+		err   string
+	}{
+		{"Plain", nil, "Cannot declare class Plain, because the name is already in use"},
+		{"A", func(decl *ClassDecl) { decl.Traits = []string{"Plain"} }, "A cannot use Plain - it is not a trait"},
+		{"B", func(decl *ClassDecl) { decl.Parent = "Countable2" }, "Class B cannot extend interface Countable2"},
+		{"C", func(decl *ClassDecl) { decl.Interfaces = []string{"Plain"} }, "C cannot implement Plain - it is not an interface"},
+		{"D", func(decl *ClassDecl) { decl.Interfaces = []string{"Countable2"} },
+			"Class D contains 1 abstract method and must therefore be declared abstract or implement the remaining methods (Countable2::count)"},
+	}
+	for _, tt := range tests {
+		decl := newClassDecl(tt.name)
+		if tt.setup != nil {
+			tt.setup(decl)
+		}
+		err := declareClass(vm, decl)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: expected error %q, got %v", tt.name, tt.err, err)
+		}
+	}
+
+	err := declareClass(vm, &ClassDecl{Class: types.NewClassEntry("E"), Parent: "Missing"})
+	thrown, ok := err.(*ThrowableError)
+	if !ok || throwableProperty(thrown.Object, "message").ToString() != `Class "Missing" not found` {
+		t.Errorf("Expected an Error for a missing parent, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// Operands of the destructor tests; constants are "D", "a;", "b;", the
+// function name and null
+var (
+	constD    = Operand{Type: OpConst, Value: 0}
+	constA    = Operand{Type: OpConst, Value: 1}
+	constB    = Operand{Type: OpConst, Value: 2}
+	constFunc = Operand{Type: OpConst, Value: 3}
+	constNull = Operand{Type: OpConst, Value: 4}
+	cv0       = Operand{Type: OpCV, Value: 0}
+	tmp       = Operand{Type: OpTmpVar, Value: 5}
+)
+
+// newDestructorVM creates a VM with the class D echoing "~D;" when
+// destroyed
+func newDestructorVM() *VM {
+	vm := New()
+	vm.constants = []interface{}{"D", "a;", "b;", "make", nil}
+	newDestructibleClass(vm, "D")
+	return vm
+}
+
+// newD emits: $0 = new D;
+func newD() Instructions {
+	return Instructions{
+		{Opcode: OpNew, Op1: constD, Result: tmp},
+		{Opcode: OpAssign, Op1: cv0, Op2: tmp, Result: cv0},
+		{Opcode: OpFree, Op1: tmp},
+	}
+}
+
+func runDestructorTest(t *testing.T, vm *VM, instrs Instructions, expected string) {
+	t.Helper()
+	fn := &CompiledFunction{Name: "main", Instructions: instrs, NumLocals: 10}
+	if err := runMain(vm, fn); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm.GetOutput() != expected {
+		t.Errorf("output %q, want %q", vm.GetOutput(), expected)
+	}
+}
+
+func TestDestruct_Unset(t *testing.T) {
+	vm := newDestructorVM()
+	instrs := append(newD(),
+		Instruction{Opcode: OpEcho, Op1: constA},
+		Instruction{Opcode: OpUnsetCV, Op1: cv0},
+		Instruction{Opcode: OpEcho, Op1: constB},
+	)
+	runDestructorTest(t, vm, instrs, "a;~D;b;")
+	if len(vm.destructibles) != 0 {
+		t.Errorf("destroyed object still tracked: %v", vm.destructibles)
+	}
+}
+
+func TestDestruct_Reassignment(t *testing.T) {
+	vm := newDestructorVM()
+	instrs := append(newD(),
+		Instruction{Opcode: OpAssign, Op1: cv0, Op2: constNull, Result: cv0},
+		Instruction{Opcode: OpEcho, Op1: constA},
+	)
+	runDestructorTest(t, vm, instrs, "~D;a;")
+}
+
+func TestDestruct_StillHeld(t *testing.T) {
+	vm := newDestructorVM()
+
+	// $1 = [$0]; unset($0); the array still holds the object
+	instrs := append(newD(),
+		Instruction{Opcode: OpInitArray, Result: Operand{Type: OpCV, Value: 1}},
+		Instruction{Opcode: OpAddArrayElement, Op1: cv0, Result: Operand{Type: OpCV, Value: 1}},
+		Instruction{Opcode: OpUnsetCV, Op1: cv0},
+		Instruction{Opcode: OpEcho, Op1: constA},
+		Instruction{Opcode: OpUnsetCV, Op1: Operand{Type: OpCV, Value: 1}},
+		Instruction{Opcode: OpEcho, Op1: constB},
+	)
+	runDestructorTest(t, vm, instrs, "a;~D;b;")
+}
+
+func TestDestruct_FunctionScope(t *testing.T) {
+	vm := newDestructorVM()
+
+	// function make() { $o = new D; return 1; }
+	body := append(newD(), Instruction{Opcode: OpReturn, Op1: constA})
+	vm.RegisterFunction("make", &CompiledFunction{Name: "make", Instructions: body, NumLocals: 10})
+
+	// make(); echo "b;";
+	runDestructorTest(t, vm, Instructions{
+		{Opcode: OpInitFcall, Op2: constFunc},
+		{Opcode: OpDoFcall, Result: tmp},
+		{Opcode: OpEcho, Op1: constB},
+	}, "~D;b;")
+}
+
+func TestDestruct_ReturnedObjectSurvives(t *testing.T) {
+	vm := newDestructorVM()
+
+	// function make() { $o = new D; return $o; }
+	body := append(newD(), Instruction{Opcode: OpReturn, Op1: cv0})
+	vm.RegisterFunction("make", &CompiledFunction{Name: "make", Instructions: body, NumLocals: 10})
+
+	// $o = make(); echo "a;"; $o = null; echo "b;";
+	runDestructorTest(t, vm, Instructions{
+		{Opcode: OpInitFcall, Op2: constFunc},
+		{Opcode: OpDoFcall, Result: tmp},
+		{Opcode: OpAssign, Op1: cv0, Op2: tmp, Result: cv0},
+		{Opcode: OpFree, Op1: tmp},
+		{Opcode: OpEcho, Op1: constA},
+		{Opcode: OpAssign, Op1: cv0, Op2: constNull, Result: cv0},
+		{Opcode: OpEcho, Op1: constB},
+	}, "a;~D;b;")
+}
+
+func TestDestruct_ExceptionInDestructor(t *testing.T) {
+	vm := newDestructorVM()
+	class := types.NewClassEntry("Bomb")
+	addNativeMethod(class, "__destruct", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return nil, vm.ThrowError("RuntimeException", "boom")
+	})
+	vm.classes["Bomb"] = class
+	vm.constants[0] = "Bomb"
+
+	instrs := append(newD(),
+		Instruction{Opcode: OpUnsetCV, Op1: cv0},
+		Instruction{Opcode: OpEcho, Op1: constA},
+	)
+	err := runMain(vm, &CompiledFunction{Name: "main", Instructions: instrs, NumLocals: 10})
+	expectThrown(t, err, "RuntimeException", "boom")
+	if vm.GetOutput() != "" {
+		t.Errorf("execution continued after the destructor threw: %q", vm.GetOutput())
+	}
+}
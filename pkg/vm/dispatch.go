@@ -0,0 +1,286 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Instruction Dispatch
+// ============================================================================
+
+// opcodeHandler executes one instruction of a frame
+type opcodeHandler func(vm *VM, frame *Frame, instr Instruction) error
+
+// opcodeHandlers is the handler table the VM loop indexes with the opcode
+// of each instruction; opcodes without a handler are nil. It is filled in
+// init, as the handlers reach the loop again through calls.
+var opcodeHandlers [OpcodeLast + 1]opcodeHandler
+
+func init() {
+	h := &opcodeHandlers
+	h[OpNop] = (*VM).opNop
+
+	// Arithmetic operations
+	h[OpAdd] = (*VM).opAdd
+	h[OpSub] = (*VM).opSub
+	h[OpMul] = (*VM).opMul
+	h[OpDiv] = (*VM).opDiv
+	h[OpMod] = (*VM).opMod
+	h[OpPow] = (*VM).opPow
+
+	// Comparison operations
+	h[OpIsEqual] = (*VM).opIsEqual
+	h[OpIsNotEqual] = (*VM).opIsNotEqual
+	h[OpIsIdentical] = (*VM).opIsIdentical
+	h[OpIsNotIdentical] = (*VM).opIsNotIdentical
+	h[OpIsSmaller] = (*VM).opIsSmaller
+	h[OpIsSmallerOrEqual] = (*VM).opIsSmallerOrEqual
+	h[OpSpaceship] = (*VM).opSpaceship
+
+	// Bitwise operations
+	h[OpBWAnd] = (*VM).opBWAnd
+	h[OpBWOr] = (*VM).opBWOr
+	h[OpBWXor] = (*VM).opBWXor
+	h[OpBWNot] = (*VM).opBWNot
+	h[OpSL] = (*VM).opShiftLeft
+	h[OpSR] = (*VM).opShiftRight
+
+	// Logical operations
+	h[OpBoolNot] = (*VM).opBoolNot
+	h[OpBool] = (*VM).opBool
+	h[OpBoolXor] = (*VM).opBoolXor
+
+	// Types
+	h[OpTypeCheck] = (*VM).opTypeCheck
+	h[OpCast] = (*VM).opCast
+
+	// Constants
+	h[OpFetchConstant] = (*VM).opFetchConstant
+	h[OpDeclareConst] = (*VM).opDeclareConst
+	h[OpQMAssign] = (*VM).opQMAssign
+
+	// Variables
+	h[OpAssign] = (*VM).opAssign
+	h[OpAssignRef] = (*VM).opAssignRef
+	h[OpFetchR] = (*VM).opFetch
+	h[OpFetchIs] = (*VM).opFetchIs
+
+	// Control flow
+	h[OpJmp] = (*VM).opJmp
+	h[OpJmpZ] = (*VM).opJmpZ
+	h[OpJmpNZ] = (*VM).opJmpNZ
+	h[OpCoalesce] = (*VM).opCoalesce
+	h[OpJmpNull] = (*VM).opJmpNull
+	h[OpBindGlobal] = (*VM).opBindGlobal
+	h[OpFetchGlobals] = (*VM).opFetchGlobals
+	h[OpBindStatic] = (*VM).opBindStatic
+	h[OpBindInitStaticOrJmp] = (*VM).opBindInitStaticOrJmp
+	h[OpSwitchLong] = (*VM).opSwitchLong
+	h[OpSwitchString] = (*VM).opSwitchString
+	h[OpMatch] = (*VM).opMatch
+
+	// Foreach loops
+	h[OpFeResetR] = (*VM).opFeResetR
+	h[OpFeResetRW] = (*VM).opFeResetRW
+	h[OpFeFetchR] = (*VM).opFeFetchR
+	h[OpFeFetchRW] = (*VM).opFeFetchRW
+	h[OpFeFree] = (*VM).opFeFree
+	h[OpCaseStrict] = (*VM).opCaseStrict
+	h[OpMatchError] = (*VM).opMatchError
+
+	// Functions
+	h[OpReturn] = (*VM).opReturn
+	h[OpRecv] = (*VM).opRecv
+	h[OpRecvInit] = (*VM).opRecvInit
+	h[OpRecvVariadic] = (*VM).opRecvVariadic
+	h[OpVerifyReturnType] = (*VM).opVerifyReturnType
+	h[OpInitFcall] = (*VM).opInitFcall
+	h[OpSendVal] = (*VM).opSendVal
+	h[OpSendRef] = (*VM).opSendRef
+	h[OpSendVarEx] = (*VM).opSendVarEx
+	h[OpSendUnpack] = (*VM).opSendUnpack
+	h[OpDoFcall] = (*VM).opDoFcall
+	h[OpDoUcall] = (*VM).opDoUcall
+	h[OpDoIcall] = (*VM).opDoIcall
+	h[OpInitFcallByName] = (*VM).opInitFcallByName
+	h[OpInitDynamicCall] = (*VM).opInitDynamicCall
+	h[OpInitNsFcallByName] = (*VM).opInitNsFcallByName
+	h[OpCallableConvert] = (*VM).opCallableConvert
+
+	// Include/require
+	h[OpIncludeOrEval] = (*VM).opIncludeOrEval
+
+	// Exceptions
+	h[OpThrow] = (*VM).opThrow
+	h[OpCatch] = (*VM).opCatch
+	h[OpFastCall] = (*VM).opFastCall
+	h[OpFastRet] = (*VM).opFastRet
+	h[OpDiscardException] = (*VM).opDiscardException
+
+	// Exit
+	h[OpExit] = (*VM).opExit
+	h[OpTicks] = (*VM).opTicks
+
+	// Error suppression
+	h[OpBeginSilence] = (*VM).opBeginSilence
+	h[OpEndSilence] = (*VM).opEndSilence
+
+	// I/O
+	h[OpEcho] = (*VM).opEcho
+
+	// String operations
+	h[OpConcat] = (*VM).opConcat
+	h[OpFastConcat] = (*VM).opFastConcat
+	h[OpRopeInit] = (*VM).opRopeInit
+	h[OpRopeAdd] = (*VM).opRopeAdd
+	h[OpRopeEnd] = (*VM).opRopeEnd
+
+	// Array operations
+	h[OpInitArray] = (*VM).opInitArray
+	h[OpAddArrayElement] = (*VM).opAddArrayElement
+	h[OpFetchDimR] = (*VM).opFetchDimR
+	h[OpFetchDimW] = (*VM).opFetchDimW
+	h[OpFetchDimRW] = (*VM).opFetchDimRW
+	h[OpFetchDimIs] = (*VM).opFetchDimIs
+	h[OpFetchDimFuncArg] = (*VM).opFetchDimFuncArg
+	h[OpFetchDimUnset] = (*VM).opFetchDimUnset
+	h[OpFetchListR] = (*VM).opFetchListR
+	h[OpFetchListW] = (*VM).opFetchListW
+	h[OpAssignDim] = (*VM).opAssignDim
+	h[OpAssignOp] = (*VM).opAssignOp
+	h[OpPreInc] = (*VM).opPreInc
+	h[OpPreDec] = (*VM).opPreDec
+	h[OpPostInc] = (*VM).opPostInc
+	h[OpPostDec] = (*VM).opPostDec
+	h[OpAssignDimOp] = (*VM).opAssignDimOp
+	h[OpFree] = (*VM).opFree
+	h[OpUnsetVar] = (*VM).opUnset
+	h[OpUnsetDim] = (*VM).opUnsetDim
+	h[OpUnsetCV] = (*VM).opUnset
+	h[OpIssetIsemptyCV] = (*VM).opIssetIsemptyCV
+	h[OpIssetIsemptyVar] = (*VM).opIssetIsemptyVar
+	h[OpFetchStaticPropR] = (*VM).opFetchStaticPropR
+	h[OpAssignStaticProp] = (*VM).opAssignStaticProp
+	h[OpUnsetStaticProp] = (*VM).opUnsetStaticProp
+	h[OpIssetIsemptyStaticProp] = (*VM).opIssetIsemptyStaticProp
+	h[OpIssetIsemptyDimObj] = (*VM).opIssetIsemptyDimObj
+	h[OpCount] = (*VM).opCount
+	h[OpInArray] = (*VM).opInArray
+	h[OpArrayKeyExists] = (*VM).opArrayKeyExists
+
+	// Closure operations
+	h[OpDeclareLambdaFunction] = (*VM).opDeclareLambdaFunction
+	h[OpBindLexical] = (*VM).opBindLexical
+
+	// Object property operations - Fetch
+	h[OpFetchObjR] = (*VM).opFetchObjR
+	h[OpFetchObjW] = (*VM).opFetchObjW
+	h[OpFetchObjRW] = (*VM).opFetchObjRW
+	h[OpFetchObjIs] = (*VM).opFetchObjIs
+	h[OpFetchObjFuncArg] = (*VM).opFetchObjFuncArg
+	h[OpFetchObjUnset] = (*VM).opFetchObjUnset
+
+	// Object property operations - Assignment
+	h[OpAssignObj] = (*VM).opAssignObj
+	h[OpAssignObjOp] = (*VM).opAssignObjOp
+	h[OpAssignObjRef] = (*VM).opAssignObjRef
+
+	// Object property operations - Unset/Isset
+	h[OpUnsetObj] = (*VM).opUnsetObj
+	h[OpIssetIsemptyPropObj] = (*VM).opIssetIsemptyPropObj
+
+	// Object property operations - Increment/Decrement
+	h[OpPreIncObj] = (*VM).opPreIncObj
+	h[OpPreDecObj] = (*VM).opPreDecObj
+	h[OpPostIncObj] = (*VM).opPostIncObj
+	h[OpPostDecObj] = (*VM).opPostDecObj
+
+	// Object creation and method calls
+	h[OpNew] = (*VM).opNew
+	h[OpInitMethodCall] = (*VM).opInitMethodCall
+	h[OpInitStaticMethodCall] = (*VM).opInitStaticMethodCall
+	h[OpClone] = (*VM).opClone
+	h[OpInstanceof] = (*VM).opInstanceof
+	h[OpGetClass] = (*VM).opGetClass
+	h[OpGetCalledClass] = (*VM).opGetCalledClass
+	h[OpDeclareClass] = (*VM).opDeclareClass
+	h[OpDeclareFunction] = (*VM).opDeclareFunction
+	h[OpFetchClass] = (*VM).opFetchClass
+	h[OpFetchClassConstant] = (*VM).opFetchClassConstant
+	h[OpFetchClassName] = (*VM).opFetchClassName
+	h[OpFetchThis] = (*VM).opFetchThis
+}
+
+// dispatchOpcode routes an instruction to its handler
+func (vm *VM) dispatchOpcode(frame *Frame, instr Instruction) error {
+	if handler := opcodeHandlers[instr.Opcode]; handler != nil {
+		return handler(vm, frame, instr)
+	}
+	return fmt.Errorf("unknown opcode: %s", instr.Opcode)
+}
+
+// opNop does nothing
+func (vm *VM) opNop(frame *Frame, instr Instruction) error {
+	return nil
+}
+
+// ============================================================================
+// Decoded Constants
+// ============================================================================
+
+// constantValues holds the constant table of an op array converted to
+// values
+type constantValues struct {
+	source []interface{}
+	values []*types.Value // nil for entries that are not scalars
+}
+
+// constantOperandValue returns the value of a constant of a frame's op
+// array. The table is converted once per op array rather than on every
+// read: constants are scalars, which no instruction modifies in place, so
+// the instructions reading one share its value.
+func (vm *VM) constantOperandValue(frame *Frame, index int) (*types.Value, error) {
+	constants := vm.constantsOf(frame)
+	decoded := frame.constants
+	if decoded == nil || !sameConstants(decoded.source, constants) {
+		decoded = vm.decodeConstants(constants)
+		frame.constants = decoded
+	}
+	if index >= 0 && index < len(decoded.values) && decoded.values[index] != nil {
+		return decoded.values[index], nil
+	}
+	return constantValue(constants, index)
+}
+
+// decodeConstants converts a constant table, reusing the conversion of an
+// earlier frame of the same op array
+func (vm *VM) decodeConstants(constants []interface{}) *constantValues {
+	if len(constants) == 0 {
+		return &constantValues{}
+	}
+	if decoded, ok := vm.decodedConstants[&constants[0]]; ok && sameConstants(decoded.source, constants) {
+		return decoded
+	}
+	decoded := &constantValues{source: constants, values: make([]*types.Value, len(constants))}
+	for i, constant := range constants {
+		switch constant.(type) {
+		case nil, bool, int64, float64, string:
+			decoded.values[i], _ = constantValue(constants, i)
+		}
+	}
+	if vm.decodedConstants == nil {
+		vm.decodedConstants = make(map[*interface{}]*constantValues)
+	}
+	vm.decodedConstants[&constants[0]] = decoded
+	return decoded
+}
+
+// sameConstants reports whether two constant tables are the same slice
+func sameConstants(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	return len(a) == 0 || &a[0] == &b[0]
+}
@@ -0,0 +1,222 @@
+package vm
+
+import (
+	"testing"
+)
+
+// Hand-assembled programs exercising the calls of the dispatch loop,
+// including the tail calls the optimizer marks. Each benchmark reports the
+// instructions executed per second (instr/s) next to the time per run, the
+// figure to compare across changes to the loop. The compiled workloads
+// (fib, mandelbrot, array sum) are benchmarked in the compiler package.
+
+func benchCV(index uint32) Operand {
+	return Operand{Type: OpCV, Value: index}
+}
+
+func benchConst(index uint32) Operand {
+	return Operand{Type: OpConst, Value: index}
+}
+
+// runBenchProgram executes a program b.N times on one VM and checks the
+// output of the last run
+func runBenchProgram(b *testing.B, vm *VM, constants []interface{}, program Instructions, want string) {
+	vm.constants = constants
+	start := vm.instructionCount
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vm.ClearOutput()
+		if err := vm.Execute(program); err != nil {
+			b.Fatalf("Execute() error: %v", err)
+		}
+	}
+	b.StopTimer()
+
+	if got := vm.GetOutput(); got != want {
+		b.Fatalf("Expected output %q, got %q", want, got)
+	}
+	b.ReportMetric(float64(vm.instructionCount-start)/b.Elapsed().Seconds(), "instr/s")
+}
+
+// callModes are the ways a benchmark runs its self calls: as calls, or
+// as tail calls the optimizer marked
+var callModes = []struct {
+	name     string
+	extended uint32
+}{
+	{"call", 0},
+	{"tail", CallTail},
+}
+
+// BenchmarkDispatch_Factorial benchmarks the tail-recursive fact(170, 1.0),
+// 170 calls deep unless the calls run in one frame
+func BenchmarkDispatch_Factorial(b *testing.B) {
+	// function fact($n, $acc) { if ($n < 2) return $acc; return fact($n - 1, $acc * $n); }
+	n, acc, t0, t1, t2 := benchCV(0), benchCV(1), Operand{Type: OpTmpVar, Value: 0},
+		Operand{Type: OpTmpVar, Value: 1}, Operand{Type: OpTmpVar, Value: 2}
+	two, one, name := benchConst(0), benchConst(1), benchConst(2)
+	for _, mode := range callModes {
+		b.Run(mode.name, func(b *testing.B) {
+			fact := &CompiledFunction{
+				Name:      "fact",
+				NumParams: 2,
+				NumLocals: 8,
+				Variables: []string{"n", "acc"},
+				Constants: []interface{}{int64(2), int64(1), "fact"},
+				Instructions: Instructions{
+					{Opcode: OpRecv, Op1: Operand{Type: OpConst, Value: 0}, Result: n},
+					{Opcode: OpRecv, Op1: Operand{Type: OpConst, Value: 1}, Result: acc},
+					{Opcode: OpIsSmaller, Op1: n, Op2: two, Result: t0},
+					{Opcode: OpJmpZ, Op1: t0, Op2: Operand{Type: OpConst, Value: 5}},
+					{Opcode: OpReturn, Op1: acc},
+					{Opcode: OpInitFcall, Op2: name, ExtendedValue: 2},
+					{Opcode: OpSub, Op1: n, Op2: one, Result: t1},
+					{Opcode: OpSendVal, Op1: t1},
+					{Opcode: OpMul, Op1: acc, Op2: n, Result: t1},
+					{Opcode: OpSendVal, Op1: t1},
+					{Opcode: OpDoFcall, Result: t2, ExtendedValue: mode.extended},
+					{Opcode: OpReturn, Op1: t2},
+				},
+			}
+
+			vm := New()
+			vm.RegisterFunction("fact", fact)
+			runBenchProgram(b, vm, []interface{}{"fact", int64(170), 1.0}, Instructions{
+				{Opcode: OpInitFcall, Op2: benchConst(0), ExtendedValue: 2},
+				{Opcode: OpSendVal, Op1: benchConst(1)},
+				{Opcode: OpSendVal, Op1: benchConst(2)},
+				{Opcode: OpDoFcall, Result: benchCV(0)},
+				{Opcode: OpEcho, Op1: benchCV(0)},
+			}, "7.257415615308E+306")
+		})
+	}
+}
+
+// BenchmarkDispatch_FibTail benchmarks the tail-recursive fib(90), the
+// iterative form of fib as calls
+func BenchmarkDispatch_FibTail(b *testing.B) {
+	// function fib($n, $a, $b) { if ($n < 1) return $a; return fib($n - 1, $b, $a + $b); }
+	n, a, c, t0, t1, t2 := benchCV(0), benchCV(1), benchCV(2), Operand{Type: OpTmpVar, Value: 0},
+		Operand{Type: OpTmpVar, Value: 1}, Operand{Type: OpTmpVar, Value: 2}
+	one, name := benchConst(0), benchConst(1)
+	for _, mode := range callModes {
+		b.Run(mode.name, func(b *testing.B) {
+			fib := &CompiledFunction{
+				Name:      "fib",
+				NumParams: 3,
+				NumLocals: 8,
+				Variables: []string{"n", "a", "b"},
+				Constants: []interface{}{int64(1), "fib"},
+				Instructions: Instructions{
+					{Opcode: OpRecv, Op1: Operand{Type: OpConst, Value: 0}, Result: n},
+					{Opcode: OpRecv, Op1: Operand{Type: OpConst, Value: 1}, Result: a},
+					{Opcode: OpRecv, Op1: Operand{Type: OpConst, Value: 2}, Result: c},
+					{Opcode: OpIsSmaller, Op1: n, Op2: one, Result: t0},
+					{Opcode: OpJmpZ, Op1: t0, Op2: Operand{Type: OpConst, Value: 6}},
+					{Opcode: OpReturn, Op1: a},
+					{Opcode: OpInitFcall, Op2: name, ExtendedValue: 3},
+					{Opcode: OpSub, Op1: n, Op2: one, Result: t1},
+					{Opcode: OpSendVal, Op1: t1},
+					{Opcode: OpSendVal, Op1: c},
+					{Opcode: OpAdd, Op1: a, Op2: c, Result: t1},
+					{Opcode: OpSendVal, Op1: t1},
+					{Opcode: OpDoFcall, Result: t2, ExtendedValue: mode.extended},
+					{Opcode: OpReturn, Op1: t2},
+				},
+			}
+
+			vm := New()
+			vm.RegisterFunction("fib", fib)
+			runBenchProgram(b, vm, []interface{}{"fib", int64(90), int64(0), int64(1)}, Instructions{
+				{Opcode: OpInitFcall, Op2: benchConst(0), ExtendedValue: 3},
+				{Opcode: OpSendVal, Op1: benchConst(1)},
+				{Opcode: OpSendVal, Op1: benchConst(2)},
+				{Opcode: OpSendVal, Op1: benchConst(3)},
+				{Opcode: OpDoFcall, Result: benchCV(0)},
+				{Opcode: OpEcho, Op1: benchCV(0)},
+			}, "2880067194370816120")
+		})
+	}
+}
+
+// BenchmarkDispatch_TreeWalk benchmarks building a complete binary tree
+// of nested arrays, 12 levels deep, and summing its 4096 leaves: calls
+// with array arguments and results
+func BenchmarkDispatch_TreeWalk(b *testing.B) {
+	d, next, left, right, node, t0 := benchCV(0), benchCV(1), benchCV(2), benchCV(3), benchCV(4),
+		Operand{Type: OpTmpVar, Value: 0}
+	one, build := benchConst(0), benchConst(1)
+
+	// function build($d) { if ($d < 1) return 1; return [build($d - 1), build($d - 1)]; }
+	buildFn := &CompiledFunction{
+		Name:      "build",
+		NumParams: 1,
+		NumLocals: 8,
+		Variables: []string{"d", "next", "left", "right", "node"},
+		Constants: []interface{}{int64(1), "build"},
+		Instructions: Instructions{
+			{Opcode: OpRecv, Op1: Operand{Type: OpConst, Value: 0}, Result: d},
+			{Opcode: OpIsSmaller, Op1: d, Op2: one, Result: t0},
+			{Opcode: OpJmpZ, Op1: t0, Op2: Operand{Type: OpConst, Value: 4}},
+			{Opcode: OpReturn, Op1: one},
+			{Opcode: OpSub, Op1: d, Op2: one, Result: next},
+			{Opcode: OpInitFcall, Op2: build, ExtendedValue: 1},
+			{Opcode: OpSendVal, Op1: next},
+			{Opcode: OpDoFcall, Result: left},
+			{Opcode: OpInitFcall, Op2: build, ExtendedValue: 1},
+			{Opcode: OpSendVal, Op1: next},
+			{Opcode: OpDoFcall, Result: right},
+			{Opcode: OpInitArray, Result: node},
+			{Opcode: OpAddArrayElement, Op1: left, Result: node},
+			{Opcode: OpAddArrayElement, Op1: right, Result: node},
+			{Opcode: OpReturn, Op1: node},
+		},
+	}
+
+	// function walk($t, $d) { if ($d < 1) return $t; return walk($t[0], $d - 1) + walk($t[1], $d - 1); }
+	t, depth, child, sumLeft, sumRight := benchCV(0), benchCV(1), benchCV(2), benchCV(3), benchCV(4)
+	zero, walk := benchConst(2), benchConst(3)
+	walkFn := &CompiledFunction{
+		Name:      "walk",
+		NumParams: 2,
+		NumLocals: 8,
+		Variables: []string{"t", "d", "child", "left", "right", "next"},
+		Constants: []interface{}{int64(1), nil, int64(0), "walk"},
+		Instructions: Instructions{
+			{Opcode: OpRecv, Op1: Operand{Type: OpConst, Value: 0}, Result: t},
+			{Opcode: OpRecv, Op1: Operand{Type: OpConst, Value: 1}, Result: depth},
+			{Opcode: OpIsSmaller, Op1: depth, Op2: one, Result: t0},
+			{Opcode: OpJmpZ, Op1: t0, Op2: Operand{Type: OpConst, Value: 5}},
+			{Opcode: OpReturn, Op1: t},
+			{Opcode: OpSub, Op1: depth, Op2: one, Result: benchCV(5)},
+			{Opcode: OpFetchDimR, Op1: t, Op2: zero, Result: child},
+			{Opcode: OpInitFcall, Op2: walk, ExtendedValue: 2},
+			{Opcode: OpSendVal, Op1: child},
+			{Opcode: OpSendVal, Op1: benchCV(5)},
+			{Opcode: OpDoFcall, Result: sumLeft},
+			{Opcode: OpFetchDimR, Op1: t, Op2: one, Result: child},
+			{Opcode: OpInitFcall, Op2: walk, ExtendedValue: 2},
+			{Opcode: OpSendVal, Op1: child},
+			{Opcode: OpSendVal, Op1: benchCV(5)},
+			{Opcode: OpDoFcall, Result: sumRight},
+			{Opcode: OpAdd, Op1: sumLeft, Op2: sumRight, Result: t0},
+			{Opcode: OpReturn, Op1: t0},
+		},
+	}
+
+	vm := New()
+	vm.RegisterFunction("build", buildFn)
+	vm.RegisterFunction("walk", walkFn)
+	runBenchProgram(b, vm, []interface{}{"build", int64(12), "walk"}, Instructions{
+		{Opcode: OpInitFcall, Op2: benchConst(0), ExtendedValue: 1},
+		{Opcode: OpSendVal, Op1: benchConst(1)},
+		{Opcode: OpDoFcall, Result: benchCV(0)},
+		{Opcode: OpInitFcall, Op2: benchConst(2), ExtendedValue: 2},
+		{Opcode: OpSendVal, Op1: benchCV(0)},
+		{Opcode: OpSendVal, Op1: benchConst(1)},
+		{Opcode: OpDoFcall, Result: benchCV(1)},
+		{Opcode: OpEcho, Op1: benchCV(1)},
+	}, "4096")
+}
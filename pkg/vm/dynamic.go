@@ -0,0 +1,246 @@
+package vm
+
+import (
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Variable Variables
+// ============================================================================
+
+// FetchByName is set in the ExtendedValue of FETCH_R, FETCH_IS, ASSIGN,
+// ASSIGN_REF and UNSET_VAR when Op1 holds the name of the variable ($$name,
+// ${expr}) rather than its compiled variable
+const FetchByName uint32 = 2
+
+// variableIndex returns the compiled variable of a name in the function a
+// frame executes, -1 if it has none
+func (f *Frame) variableIndex(name string) int {
+	for i, variable := range f.fn.Variables {
+		if variable == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// namedSlot returns the reference slot of a variable that is not a
+// compiled variable of the frame: a global in the global scope, otherwise
+// a variable of the call created by name. With create false it returns
+// nil if the variable does not exist.
+func (vm *VM) namedSlot(frame *Frame, name string, create bool) *types.Value {
+	if frame.globalScope {
+		if !create {
+			return vm.globals[name]
+		}
+		return vm.globalSlot(name)
+	}
+	slot, ok := frame.namedVars[name]
+	if !ok && create {
+		if frame.namedVars == nil {
+			frame.namedVars = make(map[string]*types.Value)
+		}
+		slot = types.NewReference(types.NewUndef())
+		frame.namedVars[name] = slot
+	}
+	return slot
+}
+
+// lookupVariable returns the value of a variable by name; false if it is
+// undefined
+func (vm *VM) lookupVariable(frame *Frame, name string) (*types.Value, bool) {
+	if name == "this" && frame.thisObject != nil {
+		return types.NewObject(frame.thisObject), true
+	}
+
+	var value *types.Value
+	if i := frame.variableIndex(name); i >= 0 {
+		value = frame.getLocal(i)
+	} else if slot := vm.namedSlot(frame, name, false); slot != nil {
+		value = slot
+	}
+	if value == nil || value.Deref().IsUndef() {
+		return nil, false
+	}
+	return value, true
+}
+
+// assignVariable assigns a variable by name
+func (vm *VM) assignVariable(frame *Frame, name string, value *types.Value) error {
+	if name == "this" {
+		return vm.ThrowError("Error", "Cannot re-assign $this")
+	}
+	if i := frame.variableIndex(name); i >= 0 {
+		return vm.setOperandValue(frame, CVOperand(uint32(i)), value)
+	}
+	vm.namedSlot(frame, name, true).Assign(value)
+	return nil
+}
+
+// bindVariable binds a variable by name to a reference
+func (vm *VM) bindVariable(frame *Frame, name string, ref *types.Value) error {
+	if name == "this" {
+		return vm.ThrowError("Error", "Cannot re-assign $this")
+	}
+	switch i := frame.variableIndex(name); {
+	case i >= 0:
+		frame.setLocal(i, ref)
+	case frame.globalScope:
+		vm.globals[name] = ref
+	default:
+		if frame.namedVars == nil {
+			frame.namedVars = make(map[string]*types.Value)
+		}
+		frame.namedVars[name] = ref
+	}
+	return nil
+}
+
+// unsetVariable unsets a variable by name
+func (vm *VM) unsetVariable(frame *Frame, name string) {
+	if i := frame.variableIndex(name); i >= 0 {
+		vm.releaseReference(frame.getLocal(i))
+		frame.setLocal(i, types.NewUndef())
+	}
+	if frame.globalScope {
+		vm.releaseReference(vm.globals[name])
+		delete(vm.globals, name)
+	} else {
+		vm.releaseReference(frame.namedVars[name])
+		delete(frame.namedVars, name)
+	}
+}
+
+// operandName returns the variable name held by an operand
+func (vm *VM) operandName(frame *Frame, op Operand) (string, error) {
+	name, err := vm.getOperandValue(frame, op)
+	if err != nil {
+		return "", err
+	}
+	return name.ToString(), nil
+}
+
+// fetchByName reads a variable by name: FETCH_R, or FETCH_IS (quiet) for
+// isset(), empty() and ??
+func (vm *VM) fetchByName(frame *Frame, instr Instruction, quiet bool) error {
+	name, err := vm.operandName(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	value, ok := vm.lookupVariable(frame, name)
+	if !ok {
+		if !quiet {
+			vm.warning("Undefined variable $%s", name)
+		}
+		value = types.NewNull()
+	}
+	return vm.setOperandValue(frame, instr.Result, value.Deref())
+}
+
+// frameScope exposes the variables of a frame to the standard library
+// (compact(), extract()) as a stdlib.Scope
+type frameScope struct {
+	vm    *VM
+	frame *Frame
+}
+
+// LookupVariable returns the value of a variable of the frame
+func (s frameScope) LookupVariable(name string) (*types.Value, bool) {
+	return s.vm.lookupVariable(s.frame, name)
+}
+
+// AssignVariable assigns a variable of the frame
+func (s frameScope) AssignVariable(name string, value *types.Value) error {
+	return s.vm.assignVariable(s.frame, name, value)
+}
+
+// BindVariable binds a variable of the frame to a reference
+func (s frameScope) BindVariable(name string, ref *types.Value) error {
+	return s.vm.bindVariable(s.frame, name, ref)
+}
+
+// Warning emits a warning
+func (s frameScope) Warning(message string) {
+	s.vm.warning("%s", message)
+}
+
+// opIssetIsemptyVar handles isset($$name) and empty($$name)
+// Op1: variable name, ExtendedValue: IssetIsEmpty for empty()
+func (vm *VM) opIssetIsemptyVar(frame *Frame, instr Instruction) error {
+	name, err := vm.operandName(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	value, exists := vm.lookupVariable(frame, name)
+	return vm.setOperandValue(frame, instr.Result, issetOrEmpty(value, exists, instr))
+}
+
+// ============================================================================
+// Dynamic Calls
+// ============================================================================
+
+// opInitDynamicCall initializes a call to a callable value: $f(),
+// $callback(), "Class::method"(), [$obj, 'method']()
+// Op1: the callable
+// In "Class::method" strings, self, parent and static are resolved in the
+// calling scope. Values that cannot be called throw an Error.
+func (vm *VM) opInitDynamicCall(frame *Frame, instr Instruction) error {
+	callable, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	callable = callable.Deref()
+
+	var target *callTarget
+	switch {
+	case callable.IsString() && strings.Contains(callable.ToString(), "::"):
+		className, method, _ := strings.Cut(callable.ToString(), "::")
+		class, err := vm.classReference(frame, strings.TrimPrefix(className, "\\"))
+		if err != nil {
+			return err
+		}
+		if class == nil {
+			return vm.ThrowError("Error", "Class \"%s\" not found", strings.TrimPrefix(className, "\\"))
+		}
+		target, err = vm.resolveMethodCallable(types.NewString(class.Name), method)
+		if err != nil {
+			return vm.ThrowError("Error", "%s", err.Error())
+		}
+	case callable.IsString(), callable.IsArray(), callable.IsObject():
+		if target, err = vm.resolveCallable(callable); err != nil {
+			if _, thrown := err.(*ThrowableError); thrown {
+				return err
+			}
+			return vm.ThrowError("Error", "%s", err.Error())
+		}
+	default:
+		return vm.ThrowError("Error", "Value not callable")
+	}
+
+	frame.suspendPendingCall()
+	frame.pendingCall = target
+	frame.pendingParams = &CallParams{
+		params: make([]*types.Value, 0, 4),
+	}
+	return nil
+}
+
+// ============================================================================
+// Dynamic Class References
+// ============================================================================
+
+// classOperand resolves the class of new $class, $class::method() and
+// $class::$prop: an object stands for its class, a string is a class name
+// (self, parent and static included). It returns nil for an unknown class.
+func (vm *VM) classOperand(frame *Frame, value *types.Value) (*types.ClassEntry, error) {
+	value = value.Deref()
+	switch {
+	case value.IsObject():
+		return value.ToObject().ClassEntry, nil
+	case value.IsString():
+		return vm.classReference(frame, strings.TrimPrefix(value.ToString(), "\\"))
+	}
+	return nil, vm.ThrowError("Error", "Class name must be a valid object or a string")
+}
@@ -0,0 +1,187 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestVariableVariables_CompiledAndNamed(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"x", int64(5), "y", int64(7)}
+
+	// function f() { ${'x'} = 5; ${'y'} = 7; echo $x, ${'y'}; $a = isset(${'y'}); unset(${'y'}); $b = isset(${'y'}); }
+	fn := &CompiledFunction{Name: "f", NumLocals: 10, Variables: []string{"x"}, Instructions: Instructions{
+		{Opcode: OpAssign, ExtendedValue: FetchByName, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 5}},
+		{Opcode: OpAssign, ExtendedValue: FetchByName, Op1: Operand{Type: OpConst, Value: 2}, Op2: Operand{Type: OpConst, Value: 3}, Result: Operand{Type: OpTmpVar, Value: 5}},
+		{Opcode: OpEcho, Op1: Operand{Type: OpCV, Value: 0}},
+		{Opcode: OpFetchR, ExtendedValue: FetchByName, Op1: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpTmpVar, Value: 6}},
+		{Opcode: OpEcho, Op1: Operand{Type: OpTmpVar, Value: 6}},
+		{Opcode: OpIssetIsemptyVar, Op1: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpTmpVar, Value: 7}},
+		{Opcode: OpUnsetVar, ExtendedValue: FetchByName, Op1: Operand{Type: OpConst, Value: 2}},
+		{Opcode: OpIssetIsemptyVar, Op1: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpTmpVar, Value: 8}},
+	}}
+
+	frame := NewFrame(fn)
+	vm.pushFrame(frame)
+	if err := vm.runFrame(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := vm.GetOutput(); got != "57" {
+		t.Errorf("Expected the compiled and the named variable to be assigned, got %q", got)
+	}
+	if !frame.getLocal(fn.tempSlot(7)).ToBool() || frame.getLocal(fn.tempSlot(8)).ToBool() {
+		t.Error("Expected isset($$name) to follow unset($$name)")
+	}
+	if _, ok := vm.globals["y"]; ok {
+		t.Error("Expected a variable created by name in a function not to be global")
+	}
+}
+
+func TestVariableVariables_GlobalScope(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"count", int64(3), "missing"}
+
+	fn := mainScript(Instructions{
+		{Opcode: OpAssign, ExtendedValue: FetchByName, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 0}},
+		{Opcode: OpFetchIs, ExtendedValue: FetchByName, Op1: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpTmpVar, Value: 1}},
+		{Opcode: OpFetchR, ExtendedValue: FetchByName, Op1: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpTmpVar, Value: 1}},
+	})
+	frame := NewFrame(fn)
+	vm.pushFrame(frame)
+	vm.bindGlobalScope(frame)
+	if err := vm.runFrame(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if slot, ok := vm.globals["count"]; !ok || slot.Deref().ToInt() != 3 {
+		t.Errorf("Expected $$name to assign the global $count, got %v", slot)
+	}
+	if got := vm.GetOutput(); strings.Count(got, "Undefined variable $missing") != 1 {
+		t.Errorf("Expected a single undefined variable warning, got %q", got)
+	}
+}
+
+func TestVariableVariables_ThisIsReadOnly(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"this", int64(1)}
+
+	err := runMain(vm, mainScript(Instructions{
+		{Opcode: OpAssign, ExtendedValue: FetchByName, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 0}},
+	}))
+	expectThrown(t, err, "Error", "Cannot re-assign $this")
+}
+
+// counterClass registers: class Counter { public static $count = 0; private static $secret = 1; }
+func counterClass(vm *VM) *types.ClassEntry {
+	class := types.NewClassEntry("Counter")
+	class.Properties["count"] = &types.PropertyDef{Name: "count", Visibility: types.VisibilityPublic, IsStatic: true, DeclaringClass: "Counter"}
+	class.Properties["secret"] = &types.PropertyDef{Name: "secret", Visibility: types.VisibilityPrivate, IsStatic: true, DeclaringClass: "Counter"}
+	class.SetStaticProperty("count", types.NewInt(0))
+	class.SetStaticProperty("secret", types.NewInt(1))
+	vm.RegisterClass(class)
+	return class
+}
+
+func TestStaticProperties_FetchAndAssign(t *testing.T) {
+	vm := New()
+	class := counterClass(vm)
+	vm.constants = []interface{}{"Counter", "count", int64(9)}
+
+	// Counter::$count = 9; echo $class::$count where $class is an object
+	err := runMain(vm, mainScript(Instructions{
+		{Opcode: OpAssignStaticProp, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpConst, Value: 2}},
+		{Opcode: OpNew, Op1: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 0}},
+		{Opcode: OpFetchStaticPropR, Op1: Operand{Type: OpTmpVar, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 1}},
+		{Opcode: OpEcho, Op1: Operand{Type: OpTmpVar, Value: 1}},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := vm.GetOutput(); got != "9" {
+		t.Errorf("Expected 9, got %q", got)
+	}
+	if value, _ := class.GetStaticProperty("count"); value.Deref().ToInt() != 9 {
+		t.Errorf("Expected Counter::$count to be 9, got %v", value)
+	}
+}
+
+func TestStaticProperties_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		class   interface{}
+		message string
+	}{
+		{"missing", "Counter", "Access to undeclared static property Counter::$missing"},
+		{"secret", "Counter", "Cannot access private property Counter::$secret"},
+		{"count", int64(1), "Class name must be a valid object or a string"},
+		{"count", "Nope", "Class \"Nope\" not found"},
+	}
+
+	for _, tt := range tests {
+		vm := New()
+		counterClass(vm)
+		vm.constants = []interface{}{tt.class, tt.name}
+		err := runMain(vm, mainScript(Instructions{
+			{Opcode: OpFetchStaticPropR, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 0}},
+		}))
+		expectThrown(t, err, "Error", tt.message)
+	}
+}
+
+func TestStaticProperties_InitializerIgnoresVisibility(t *testing.T) {
+	vm := New()
+	class := counterClass(vm)
+	vm.constants = []interface{}{"Counter", "secret", int64(2)}
+
+	err := runMain(vm, mainScript(Instructions{
+		{Opcode: OpAssignStaticProp, ExtendedValue: StaticPropInit, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpConst, Value: 2}},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, _ := class.GetStaticProperty("secret"); value.Deref().ToInt() != 2 {
+		t.Errorf("Expected the initializer to assign the private property, got %v", value)
+	}
+}
+
+func TestDynamicCall_Callables(t *testing.T) {
+	vm := New()
+	vm.RegisterBuiltin("shout", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return types.NewString(strings.ToUpper(args[0].ToString())), nil
+	})
+	vm.constants = []interface{}{"shout", "abc"}
+
+	// $f = 'shout'; echo $f('abc');
+	err := runMain(vm, mainScript(Instructions{
+		{Opcode: OpInitDynamicCall, Op1: Operand{Type: OpConst, Value: 0}},
+		{Opcode: OpSendVal, Op1: Operand{Type: OpConst, Value: 1}},
+		{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 0}},
+		{Opcode: OpEcho, Op1: Operand{Type: OpTmpVar, Value: 0}},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := vm.GetOutput(); got != "ABC" {
+		t.Errorf("Expected ABC, got %q", got)
+	}
+}
+
+func TestDynamicCall_Errors(t *testing.T) {
+	tests := []struct {
+		callable interface{}
+		message  string
+	}{
+		{int64(42), "Value not callable"},
+		{"Missing::run", "Class \"Missing\" not found"},
+	}
+
+	for _, tt := range tests {
+		vm := New()
+		vm.constants = []interface{}{tt.callable}
+		err := runMain(vm, mainScript(Instructions{
+			{Opcode: OpInitDynamicCall, Op1: Operand{Type: OpConst, Value: 0}},
+		}))
+		expectThrown(t, err, "Error", tt.message)
+	}
+}
@@ -0,0 +1,137 @@
+package vm
+
+import (
+	"math"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Enums
+// ============================================================================
+
+// registerEnumInterfaces registers the UnitEnum and BackedEnum interfaces
+// that every enum implements
+func (vm *VM) registerEnumInterfaces() {
+	unitEnum, backedEnum := enumInterfaces()
+	for _, iface := range []*types.InterfaceEntry{unitEnum, backedEnum} {
+		vm.classes[iface.Name] = interfaceClass(iface)
+	}
+}
+
+// enumInterfaces returns the UnitEnum interface and BackedEnum, which
+// extends it
+func enumInterfaces() (unitEnum, backedEnum *types.InterfaceEntry) {
+	unitEnum = types.NewInterfaceEntry("UnitEnum")
+	unitEnum.Methods["cases"] = &types.MethodDef{
+		Name: "cases", Visibility: types.VisibilityPublic, IsStatic: true, IsAbstract: true,
+	}
+
+	backedEnum = types.NewInterfaceEntry("BackedEnum")
+	backedEnum.ParentInterfaces = append(backedEnum.ParentInterfaces, unitEnum)
+	for _, name := range []string{"from", "tryFrom"} {
+		backedEnum.Methods[name] = &types.MethodDef{
+			Name: name, Visibility: types.VisibilityPublic, IsStatic: true, IsAbstract: true, NumParams: 1,
+		}
+	}
+	return unitEnum, backedEnum
+}
+
+// initEnum completes an enum being registered: it is final, implements
+// UnitEnum (and BackedEnum when backed), has its cases as class constants
+// and gets the static cases(), from() and tryFrom() methods.
+func (vm *VM) initEnum(class *types.ClassEntry) {
+	class.IsFinal = true
+
+	unitEnum, backedEnum := enumInterfaces()
+	if class.EnumBackingType != "" {
+		class.Interfaces = append(class.Interfaces, backedEnum)
+	} else {
+		class.Interfaces = append(class.Interfaces, unitEnum)
+	}
+
+	for _, name := range class.GetCases() {
+		object, _ := class.EnumCase(name)
+		class.Constants[name] = &types.ClassConstant{Name: name, Value: object, Visibility: types.VisibilityPublic, IsFinal: true}
+	}
+
+	addNativeMethod(class, "cases", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		cases := types.NewEmptyArray()
+		for _, name := range class.GetCases() {
+			object, _ := class.EnumCase(name)
+			cases.Append(object)
+		}
+		return types.NewArray(cases), nil
+	}).IsStatic = true
+
+	if class.EnumBackingType == "" {
+		return
+	}
+	addNativeMethod(class, "from", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return vm.enumFrom(class, "from", args, false)
+	}).IsStatic = true
+	addNativeMethod(class, "tryFrom", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return vm.enumFrom(class, "tryFrom", args, true)
+	}).IsStatic = true
+}
+
+// enumFrom implements BackedEnum::from() and tryFrom(): the argument is
+// coerced to the backing type, and a value no case has is a ValueError,
+// or null for tryFrom()
+func (vm *VM) enumFrom(class *types.ClassEntry, method string, args []*types.Value, try bool) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, vm.ThrowError("ArgumentCountError", "%s::%s() expects exactly 1 argument, 0 given", class.Name, method)
+	}
+
+	value, ok := enumBackingValue(class.EnumBackingType, args[0].Deref())
+	if !ok {
+		return nil, vm.ThrowError("TypeError", "%s::%s(): Argument #1 ($value) must be of type %s, %s given",
+			class.Name, method, class.EnumBackingType, args[0].Deref().TypeString())
+	}
+
+	if object, found := class.EnumCaseFor(value); found {
+		return object, nil
+	}
+	if try {
+		return types.NewNull(), nil
+	}
+
+	shown := value.ToString()
+	if value.IsString() {
+		shown = `"` + shown + `"`
+	}
+	return nil, vm.ThrowError("ValueError", "%s is not a valid backing value for enum %s", shown, class.Name)
+}
+
+// enumBackingValue coerces a from()/tryFrom() argument to the backing type
+// of an enum, as a non-strict int or string parameter would
+func enumBackingValue(backingType string, value *types.Value) (*types.Value, bool) {
+	switch value.Type() {
+	case types.TypeInt:
+		if backingType == "int" {
+			return value, true
+		}
+		return types.NewString(value.ToString()), true
+	case types.TypeFloat:
+		if backingType == "string" {
+			return types.NewString(value.ToString()), true
+		}
+		// Fractional floats are truncated; NAN and INF are not ints
+		if f := value.ToFloat(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return types.NewInt(int64(f)), true
+		}
+	case types.TypeString:
+		if backingType == "string" {
+			return value, true
+		}
+		if number, kind := types.ParseNumeric(value.ToString()); kind == types.Numeric {
+			return enumBackingValue(backingType, number)
+		}
+	case types.TypeBool:
+		if backingType == "int" {
+			return types.NewInt(value.ToInt()), true
+		}
+		return types.NewString(value.ToString()), true
+	}
+	return nil, false
+}
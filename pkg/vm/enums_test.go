@@ -0,0 +1,131 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// newSuitEnum registers the string-backed enum Suit with cases Hearts ("H")
+// and Spades ("S")
+func newSuitEnum(vm *VM) *types.ClassEntry {
+	class := types.NewEnumEntry("Suit", "string")
+	class.AddCase("Hearts", types.NewString("H"))
+	class.AddCase("Spades", types.NewString("S"))
+	vm.RegisterClass(class)
+	return class
+}
+
+// callEnumMethod calls a native static method of an enum
+func callEnumMethod(vm *VM, class *types.ClassEntry, name string, args ...*types.Value) (*types.Value, error) {
+	return class.Methods[name].Handler.(NativeMethod)(vm, nil, args)
+}
+
+func TestEnum_Cases(t *testing.T) {
+	vm := New()
+	class := newSuitEnum(vm)
+
+	if !class.IsFinal {
+		t.Error("Expected an enum to be final")
+	}
+	if !vm.isInstanceOf(class, "BackedEnum") || !vm.isInstanceOf(class, "UnitEnum") {
+		t.Error("Expected a backed enum to implement BackedEnum and UnitEnum")
+	}
+
+	cases, err := callEnumMethod(vm, class, "cases")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cases.ToArray().Len() != 2 {
+		t.Fatalf("Expected 2 cases, got %d", cases.ToArray().Len())
+	}
+	first, _ := cases.ToArray().Get(types.NewInt(0))
+	if name := first.ToObject().Properties["name"].Value.ToString(); name != "Hearts" {
+		t.Errorf("Expected cases in declaration order, got %s first", name)
+	}
+
+	// Suit::Hearts is the same object as the one cases() returns
+	frame := NewFrame(mainScript(nil))
+	vm.constants = []interface{}{"Suit", "Hearts", "Clubs"}
+	instr := Instruction{Opcode: OpFetchClassConstant, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 10}}
+	if err := vm.dispatch(frame, instr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if frame.getLocal(10).ToObject() != first.ToObject() {
+		t.Error("Expected Suit::Hearts to be a singleton")
+	}
+
+	instr.Op2 = Operand{Type: OpConst, Value: 2}
+	err = vm.dispatch(frame, instr)
+	thrown, ok := err.(*ThrowableError)
+	if !ok || throwableProperty(thrown.Object, "message").ToString() != "Undefined constant Suit::Clubs" {
+		t.Errorf("Expected an Error for an undefined case, got %v", err)
+	}
+}
+
+func TestEnum_FromTryFrom(t *testing.T) {
+	vm := New()
+	class := newSuitEnum(vm)
+	hearts, _ := class.EnumCase("Hearts")
+
+	result, err := callEnumMethod(vm, class, "from", types.NewString("H"))
+	if err != nil || result.ToObject() != hearts.ToObject() {
+		t.Errorf("Expected Suit::from('H') to return Suit::Hearts, got %v (%v)", result, err)
+	}
+
+	result, err = callEnumMethod(vm, class, "tryFrom", types.NewString("X"))
+	if err != nil || !result.IsNull() {
+		t.Errorf("Expected Suit::tryFrom('X') to return null, got %v (%v)", result, err)
+	}
+
+	tests := []struct {
+		arg   *types.Value
+		class string
+		msg   string
+	}{
+		{types.NewString("X"), "ValueError", `"X" is not a valid backing value for enum Suit`},
+		{types.NewArray(types.NewEmptyArray()), "TypeError", "Suit::from(): Argument #1 ($value) must be of type string, array given"},
+	}
+	for _, tt := range tests {
+		_, err := callEnumMethod(vm, class, "from", tt.arg)
+		thrown, ok := err.(*ThrowableError)
+		if !ok || thrown.Object.ClassName != tt.class || throwableProperty(thrown.Object, "message").ToString() != tt.msg {
+			t.Errorf("Expected %s %q, got %v", tt.class, tt.msg, err)
+		}
+	}
+
+	// An int-backed enum coerces numeric strings
+	status := types.NewEnumEntry("Status", "int")
+	status.AddCase("Active", types.NewInt(1))
+	vm.RegisterClass(status)
+	active, _ := status.EnumCase("Active")
+	result, err = callEnumMethod(vm, status, "from", types.NewString("1"))
+	if err != nil || result.ToObject() != active.ToObject() {
+		t.Errorf("Expected Status::from('1') to return Status::Active, got %v (%v)", result, err)
+	}
+}
+
+func TestEnum_NewAndClone(t *testing.T) {
+	vm := New()
+	class := newSuitEnum(vm)
+	hearts, _ := class.EnumCase("Hearts")
+
+	frame := NewFrame(mainScript(nil))
+	vm.constants = []interface{}{"Suit"}
+	frame.setLocal(0, hearts)
+
+	tests := []struct {
+		instr Instruction
+		msg   string
+	}{
+		{Instruction{Opcode: OpNew, Op1: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 10}}, "Cannot instantiate enum Suit"},
+		{Instruction{Opcode: OpClone, Op1: Operand{Type: OpCV, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 10}}, "Trying to clone an uncloneable object of class Suit"},
+	}
+	for _, tt := range tests {
+		err := vm.dispatch(frame, tt.instr)
+		thrown, ok := err.(*ThrowableError)
+		if !ok || throwableProperty(thrown.Object, "message").ToString() != tt.msg {
+			t.Errorf("%s: expected Error %q, got %v", tt.instr.Opcode, tt.msg, err)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package vm
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Error Reporting
+// ============================================================================
+
+// fatalErrors are the levels that abort the script. The @ operator keeps
+// reporting them.
+const fatalErrors = runtime.E_ERROR | runtime.E_CORE_ERROR | runtime.E_COMPILE_ERROR |
+	runtime.E_USER_ERROR | runtime.E_RECOVERABLE_ERROR | runtime.E_PARSE
+
+// unhandledErrors are the levels never passed to a user error handler
+const unhandledErrors = runtime.E_ERROR | runtime.E_PARSE | runtime.E_CORE_ERROR |
+	runtime.E_CORE_WARNING | runtime.E_COMPILE_ERROR | runtime.E_COMPILE_WARNING
+
+// FatalError aborts the script on an unrecoverable error such as
+// trigger_error() with E_USER_ERROR. Unlike exceptions it cannot be caught.
+type FatalError struct {
+	Level   runtime.ErrorType
+	Message string
+	File    string
+	Line    int
+}
+
+// Error returns the message PHP prints for the fatal error
+func (e *FatalError) Error() string {
+	return fmt.Sprintf("%s in %s on line %d", e.Message, e.File, e.Line)
+}
+
+// ErrorListener observes the non-fatal errors the VM reports: those the
+// error_reporting level includes and no user error handler handled. Fatal
+// errors are returned to the caller of the VM as a FatalError instead.
+type ErrorListener func(level runtime.ErrorType, message, file string, line int)
+
+// errorHandler is a handler installed by set_error_handler()
+type errorHandler struct {
+	callback *types.Value
+	levels   runtime.ErrorType // Levels passed to the handler
+}
+
+// errorRecord is the last reported error, returned by error_get_last()
+type errorRecord struct {
+	level   runtime.ErrorType
+	message string
+	file    string
+	line    int
+}
+
+// errorLabel returns the label PHP displays in front of an error message
+func errorLabel(level runtime.ErrorType) string {
+	switch level {
+	case runtime.E_ERROR, runtime.E_CORE_ERROR, runtime.E_COMPILE_ERROR, runtime.E_USER_ERROR:
+		return "Fatal error"
+	case runtime.E_RECOVERABLE_ERROR:
+		return "Recoverable fatal error"
+	case runtime.E_WARNING, runtime.E_CORE_WARNING, runtime.E_COMPILE_WARNING, runtime.E_USER_WARNING:
+		return "Warning"
+	case runtime.E_PARSE:
+		return "Parse error"
+	case runtime.E_NOTICE, runtime.E_USER_NOTICE:
+		return "Notice"
+	case runtime.E_STRICT:
+		return "Strict Standards"
+	case runtime.E_DEPRECATED, runtime.E_USER_DEPRECATED:
+		return "Deprecated"
+	default:
+		return "Unknown error"
+	}
+}
+
+// SetErrorReporting sets the reported error levels (the error_reporting
+// ini setting)
+func (vm *VM) SetErrorReporting(level runtime.ErrorType) {
+	vm.config.Set("error_reporting", strconv.Itoa(int(level)), runtime.INI_SYSTEM)
+}
+
+// ErrorReporting returns the reported error levels
+func (vm *VM) ErrorReporting() runtime.ErrorType {
+	return vm.errorReporting
+}
+
+// SetDisplayErrors sets whether reported errors are written to the output
+// (the display_errors ini setting)
+func (vm *VM) SetDisplayErrors(display bool) {
+	value := "0"
+	if display {
+		value = "1"
+	}
+	vm.config.Set("display_errors", value, runtime.INI_SYSTEM)
+}
+
+// SetErrorListener sets the observer of the errors reported, whether or
+// not they are displayed
+func (vm *VM) SetErrorListener(listener ErrorListener) {
+	vm.errorListener = listener
+}
+
+// raiseError reports an error at the current instruction. A user error
+// handler accepting the level gets it first; if there is none or it
+// returns false, the error is recorded for error_get_last() and displayed
+// if error_reporting includes it. Fatal levels return a FatalError, and an
+// exception thrown by the handler is returned as well.
+func (vm *VM) raiseError(level runtime.ErrorType, message string) error {
+	file, line := vm.scriptPath, vm.currentLine()
+
+	if handler := vm.errorHandler; handler != nil && handler.levels&level != 0 && level&unhandledErrors == 0 {
+		// The handler is disabled while it runs, so errors raised inside
+		// it take the standard path
+		vm.errorHandler = nil
+		result, err := vm.CallCallable(handler.callback, []*types.Value{
+			types.NewInt(int64(level)),
+			types.NewString(message),
+			types.NewString(file),
+			types.NewInt(int64(line)),
+		})
+		if vm.errorHandler == nil {
+			vm.errorHandler = handler
+		}
+		if err != nil {
+			return err
+		}
+		if result == nil || !result.Deref().IsBool() || result.Deref().ToBool() {
+			return nil
+		}
+	}
+
+	vm.lastError = &errorRecord{level: level, message: message, file: file, line: line}
+
+	if level&fatalErrors != 0 {
+		return &FatalError{Level: level, Message: message, File: file, Line: line}
+	}
+	if vm.errorListener != nil && vm.errorReporting&level != 0 {
+		vm.errorListener(level, message, file, line)
+	}
+	if vm.displayErrors && vm.errorReporting&level != 0 {
+		vm.writeOutput([]byte(fmt.Sprintf("\n%s: %s in %s on line %d\n", errorLabel(level), message, file, line)))
+	}
+	return nil
+}
+
+// reportError raises a non-fatal engine error. Opcode handlers and builtins
+// carry on after it, so an exception thrown by the error handler is kept
+// pending until the current instruction completes.
+func (vm *VM) reportError(level runtime.ErrorType, format string, args ...interface{}) {
+	if err := vm.raiseError(level, fmt.Sprintf(format, args...)); err != nil && vm.pendingError == nil {
+		vm.pendingError = err
+	}
+}
+
+// warning reports a PHP warning at the current instruction
+func (vm *VM) warning(format string, args ...interface{}) {
+	vm.reportError(runtime.E_WARNING, format, args...)
+}
+
+// notice reports a PHP notice at the current instruction
+func (vm *VM) notice(format string, args ...interface{}) {
+	vm.reportError(runtime.E_NOTICE, format, args...)
+}
+
+// deprecated reports a deprecation at the current instruction
+func (vm *VM) deprecated(format string, args ...interface{}) {
+	vm.reportError(runtime.E_DEPRECATED, format, args...)
+}
+
+// takePendingError returns and clears the error left by reportError
+func (vm *VM) takePendingError() error {
+	err := vm.pendingError
+	vm.pendingError = nil
+	return err
+}
+
+// stringValue converts a value to string for output and concatenation,
+// warning about arrays as PHP does. Objects are converted with
+// __toString(); the others throw an Error.
+func (vm *VM) stringValue(value *types.Value) (string, error) {
+	value = value.Deref()
+	switch {
+	case value.IsArray():
+		vm.warning("Array to string conversion")
+	case value.IsObject():
+		s, ok, err := vm.stringable(value)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", vm.ThrowError("Error", "Object of class %s could not be converted to string", value.ToObject().ClassName)
+		}
+		return s, nil
+	}
+	return value.ToString(), nil
+}
+
+// stringable converts an object with __toString() to a string; false if
+// it has none
+func (vm *VM) stringable(value *types.Value) (string, bool, error) {
+	obj := value.Deref().ToObject()
+	method := magicMethodOf(obj.ClassEntry, "__toString")
+	if method == nil {
+		return "", false, nil
+	}
+	result, err := vm.invokeTarget(vm.methodTarget(obj.ClassEntry, obj, method), nil)
+	if err != nil {
+		return "", false, err
+	}
+	return result.ToString(), true, nil
+}
+
+// ============================================================================
+// Error Suppression (@)
+// ============================================================================
+
+// opBeginSilence starts an @ expression: all but fatal errors stop being
+// reported until the matching END_SILENCE. The previous level is saved on
+// the frame, so unwinding an exception can restore it.
+func (vm *VM) opBeginSilence(frame *Frame, instr Instruction) error {
+	frame.silenced = append(frame.silenced, vm.errorReporting)
+	vm.errorReporting &= fatalErrors
+	return nil
+}
+
+// opEndSilence ends an @ expression, restoring the error level unless the
+// expression itself changed it
+func (vm *VM) opEndSilence(frame *Frame, instr Instruction) error {
+	if len(frame.silenced) == 0 {
+		return nil
+	}
+	saved := frame.silenced[len(frame.silenced)-1]
+	frame.silenced = frame.silenced[:len(frame.silenced)-1]
+	vm.restoreSilence(saved)
+	return nil
+}
+
+// restoreSilence restores the error level saved by BEGIN_SILENCE
+func (vm *VM) restoreSilence(saved runtime.ErrorType) {
+	if vm.errorReporting&^fatalErrors == 0 && saved&^fatalErrors != 0 {
+		vm.errorReporting = saved
+	}
+}
+
+// unwindSilence leaves the @ expressions of a frame an exception is
+// propagating through
+func (vm *VM) unwindSilence(frame *Frame) {
+	if len(frame.silenced) > 0 {
+		vm.restoreSilence(frame.silenced[0])
+		frame.silenced = frame.silenced[:0]
+	}
+}
+
+// ============================================================================
+// Error Builtins
+// ============================================================================
+
+// registerErrorBuiltins registers the error handling functions
+func (vm *VM) registerErrorBuiltins() {
+	vm.RegisterBuiltin("error_reporting", builtinErrorReporting)
+	vm.RegisterBuiltin("set_error_handler", builtinSetErrorHandler)
+	vm.RegisterBuiltin("restore_error_handler", builtinRestoreErrorHandler)
+	vm.RegisterBuiltin("trigger_error", builtinTriggerError)
+	vm.RegisterBuiltin("user_error", builtinTriggerError)
+	vm.RegisterBuiltin("error_get_last", builtinErrorGetLast)
+	vm.RegisterBuiltin("error_clear_last", builtinErrorClearLast)
+}
+
+// error_reporting(?int $error_level = null): int
+func builtinErrorReporting(vm *VM, args []*types.Value) (*types.Value, error) {
+	old := vm.errorReporting
+	if len(args) > 0 && !args[0].Deref().IsNull() {
+		vm.config.Set("error_reporting", args[0].Deref().ToString(), runtime.INI_USER)
+	}
+	return types.NewInt(int64(old)), nil
+}
+
+// set_error_handler(?callable $callback, int $error_levels = E_ALL): ?callable
+func builtinSetErrorHandler(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("set_error_handler() expects at least 1 argument, 0 given")
+	}
+	callback := args[0].Deref()
+	if !callback.IsNull() && !vm.IsCallable(callback) {
+		return nil, vm.ThrowError("TypeError", "set_error_handler(): Argument #1 ($callback) must be a valid callback or null")
+	}
+
+	previous := types.NewNull()
+	if vm.errorHandler != nil {
+		previous = vm.errorHandler.callback
+	}
+	vm.errorHandlers = append(vm.errorHandlers, vm.errorHandler)
+
+	if callback.IsNull() {
+		vm.errorHandler = nil
+		return previous, nil
+	}
+	levels := runtime.E_ALL
+	if len(args) > 1 {
+		levels = runtime.ErrorType(args[1].Deref().ToInt())
+	}
+	vm.errorHandler = &errorHandler{callback: callback, levels: levels}
+	return previous, nil
+}
+
+// restore_error_handler(): true
+func builtinRestoreErrorHandler(vm *VM, args []*types.Value) (*types.Value, error) {
+	vm.errorHandler = nil
+	if n := len(vm.errorHandlers); n > 0 {
+		vm.errorHandler = vm.errorHandlers[n-1]
+		vm.errorHandlers = vm.errorHandlers[:n-1]
+	}
+	return types.NewBool(true), nil
+}
+
+// trigger_error(string $message, int $error_level = E_USER_NOTICE): true
+func builtinTriggerError(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("trigger_error() expects at least 1 argument, 0 given")
+	}
+	level := runtime.E_USER_NOTICE
+	if len(args) > 1 {
+		level = runtime.ErrorType(args[1].Deref().ToInt())
+	}
+	switch level {
+	case runtime.E_USER_ERROR, runtime.E_USER_WARNING, runtime.E_USER_NOTICE, runtime.E_USER_DEPRECATED:
+	default:
+		return nil, vm.ThrowError("ValueError",
+			"trigger_error(): Argument #2 ($error_level) must be one of E_USER_ERROR, E_USER_WARNING, E_USER_NOTICE, or E_USER_DEPRECATED")
+	}
+	if err := vm.raiseError(level, args[0].Deref().ToString()); err != nil {
+		return nil, err
+	}
+	return types.NewBool(true), nil
+}
+
+// error_get_last(): ?array
+func builtinErrorGetLast(vm *VM, args []*types.Value) (*types.Value, error) {
+	if vm.lastError == nil {
+		return types.NewNull(), nil
+	}
+	arr := types.NewEmptyArray()
+	arr.Set(types.NewString("type"), types.NewInt(int64(vm.lastError.level)))
+	arr.Set(types.NewString("message"), types.NewString(vm.lastError.message))
+	arr.Set(types.NewString("file"), types.NewString(vm.lastError.file))
+	arr.Set(types.NewString("line"), types.NewInt(int64(vm.lastError.line)))
+	return types.NewArray(arr), nil
+}
+
+// error_clear_last(): void
+func builtinErrorClearLast(vm *VM, args []*types.Value) (*types.Value, error) {
+	vm.lastError = nil
+	return types.NewNull(), nil
+}
@@ -0,0 +1,214 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// goHandler wraps a Go function as a PHP error handler callable
+func goHandler(fn BuiltinFunction) *types.Value {
+	return newClosureObject(&Closure{Builtin: fn})
+}
+
+func TestRaiseError_DisplayAndReporting(t *testing.T) {
+	vm := New()
+	vm.SetScriptPath("test.php")
+
+	vm.notice("first")
+	vm.deprecated("second")
+	expected := "\nNotice: first in test.php on line 0\n\nDeprecated: second in test.php on line 0\n"
+	if vm.GetOutput() != expected {
+		t.Errorf("Expected %q, got %q", expected, vm.GetOutput())
+	}
+
+	// error_reporting() returns the old level and hides excluded levels
+	old, _ := builtinErrorReporting(vm, []*types.Value{types.NewInt(int64(runtime.E_ALL &^ runtime.E_WARNING))})
+	if old.ToInt() != int64(runtime.E_ALL) {
+		t.Errorf("Expected old level E_ALL, got %d", old.ToInt())
+	}
+	before := vm.GetOutput()
+	vm.warning("hidden")
+	if vm.GetOutput() != before {
+		t.Errorf("Excluded warning should not be displayed, got %q", vm.GetOutput())
+	}
+
+	// Hidden errors are still recorded for error_get_last()
+	last, _ := builtinErrorGetLast(vm, nil)
+	message, _ := last.ToArray().Get(types.NewString("message"))
+	level, _ := last.ToArray().Get(types.NewString("type"))
+	if message.ToString() != "hidden" || level.ToInt() != int64(runtime.E_WARNING) {
+		t.Errorf("Unexpected error_get_last() result: %v, %v", message, level)
+	}
+
+	builtinErrorClearLast(vm, nil)
+	if last, _ := builtinErrorGetLast(vm, nil); !last.IsNull() {
+		t.Errorf("error_clear_last() should reset the last error, got %v", last)
+	}
+}
+
+func TestSetErrorListener(t *testing.T) {
+	vm := New()
+	vm.SetScriptPath("test.php")
+	vm.SetDisplayErrors(false)
+
+	var reported []string
+	vm.SetErrorListener(func(level runtime.ErrorType, message, file string, line int) {
+		reported = append(reported, fmt.Sprintf("%d:%s:%s", level, message, file))
+	})
+	vm.warning("seen")
+	vm.SetErrorReporting(runtime.E_ALL &^ runtime.E_NOTICE)
+	vm.notice("excluded")
+	if err := vm.raiseError(runtime.E_USER_ERROR, "fatal"); err == nil {
+		t.Fatal("Expected a FatalError")
+	}
+
+	// Fatal errors are returned, not passed to the listener
+	if len(reported) != 1 || reported[0] != fmt.Sprintf("%d:seen:test.php", runtime.E_WARNING) {
+		t.Errorf("Expected only the warning to be reported, got %v", reported)
+	}
+	if vm.GetOutput() != "" {
+		t.Errorf("Expected no displayed errors, got %q", vm.GetOutput())
+	}
+}
+
+func TestSetErrorHandler(t *testing.T) {
+	vm := New()
+	vm.SetScriptPath("test.php")
+
+	var calls []string
+	handler := goHandler(func(vm *VM, args []*types.Value) (*types.Value, error) {
+		calls = append(calls, args[0].ToString()+":"+args[1].ToString())
+		// Errors raised inside the handler take the standard path
+		vm.warning("inside")
+		return types.NewBool(true), nil
+	})
+
+	previous, err := builtinSetErrorHandler(vm, []*types.Value{handler, types.NewInt(int64(runtime.E_USER_WARNING))})
+	if err != nil || !previous.IsNull() {
+		t.Fatalf("Expected null previous handler, got %v (%v)", previous, err)
+	}
+
+	if _, err := builtinTriggerError(vm, []*types.Value{types.NewString("custom"), types.NewInt(int64(runtime.E_USER_WARNING))}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Levels outside the handler's mask are not passed to it
+	vm.warning("engine")
+
+	if len(calls) != 1 || calls[0] != "512:custom" {
+		t.Errorf("Expected one handler call, got %v", calls)
+	}
+	output := vm.GetOutput()
+	if strings.Contains(output, "custom") || !strings.Contains(output, "Warning: inside") || !strings.Contains(output, "Warning: engine") {
+		t.Errorf("Unexpected output: %q", output)
+	}
+
+	// A handler returning false falls through to the standard handler
+	builtinSetErrorHandler(vm, []*types.Value{goHandler(func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return types.NewBool(false), nil
+	})})
+	builtinTriggerError(vm, []*types.Value{types.NewString("shown")})
+	if !strings.Contains(vm.GetOutput(), "\nNotice: shown in test.php") {
+		t.Errorf("Expected the notice to be displayed, got %q", vm.GetOutput())
+	}
+
+	// restore_error_handler() reinstates the previous handler
+	builtinRestoreErrorHandler(vm, nil)
+	if vm.errorHandler == nil || vm.errorHandler.callback != handler {
+		t.Errorf("Expected the first handler to be restored")
+	}
+	builtinRestoreErrorHandler(vm, nil)
+	if vm.errorHandler != nil {
+		t.Errorf("Expected no handler after restoring twice")
+	}
+}
+
+func TestErrorHandler_ThrowingIsRaisedAfterInstruction(t *testing.T) {
+	vm := New()
+	builtinSetErrorHandler(vm, []*types.Value{goHandler(func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return nil, vm.ThrowError("ErrorException", "%s", args[1].ToString())
+	})})
+
+	// echo [] . ''; warns about the array conversion
+	arr := types.NewArray(types.NewEmptyArray())
+	frame := NewFrame(mainScript(nil))
+	frame.setLocal(0, arr)
+	err := vm.dispatch(frame, Instruction{Opcode: OpEcho, Op1: Operand{Type: OpCV, Value: 0}})
+
+	thrown, ok := err.(*ThrowableError)
+	if !ok {
+		t.Fatalf("Expected a thrown ErrorException, got %v", err)
+	}
+	if msg := throwableProperty(thrown.Object, "message").ToString(); msg != "Array to string conversion" {
+		t.Errorf("Unexpected message %q", msg)
+	}
+	if vm.pendingError != nil {
+		t.Errorf("Pending error should be cleared")
+	}
+}
+
+func TestTriggerError(t *testing.T) {
+	vm := New()
+	vm.SetScriptPath("test.php")
+
+	_, err := builtinTriggerError(vm, []*types.Value{types.NewString("bad"), types.NewInt(int64(runtime.E_WARNING))})
+	if thrown, ok := err.(*ThrowableError); !ok || thrown.Object.ClassEntry.Name != "ValueError" {
+		t.Errorf("Expected ValueError for a non-user level, got %v", err)
+	}
+
+	_, err = builtinTriggerError(vm, []*types.Value{types.NewString("stop"), types.NewInt(int64(runtime.E_USER_ERROR))})
+	fatal, ok := err.(*FatalError)
+	if !ok || fatal.Error() != "stop in test.php on line 0" {
+		t.Errorf("Expected a fatal error, got %v", err)
+	}
+}
+
+func TestSilence(t *testing.T) {
+	vm := New()
+	vm.SetScriptPath("test.php")
+
+	// @$x; $x; warns once, for the unsilenced read
+	err := vm.ExecuteScript(&Script{
+		Instructions: Instructions{
+			{Opcode: OpBeginSilence},
+			{Opcode: OpFetchR, Op1: Operand{Type: OpCV, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 10}},
+			{Opcode: OpEndSilence},
+			{Opcode: OpFetchR, Op1: Operand{Type: OpCV, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 10}},
+		},
+		Variables: []string{"x"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm.GetOutput() != "\nWarning: Undefined variable $x in test.php on line 0\n" {
+		t.Errorf("Expected a single warning, got %q", vm.GetOutput())
+	}
+	if vm.ErrorReporting() != runtime.E_ALL {
+		t.Errorf("Expected error_reporting to be restored, got %d", vm.ErrorReporting())
+	}
+	if last, _ := builtinErrorGetLast(vm, nil); last.IsNull() {
+		t.Errorf("Silenced errors should still be recorded")
+	}
+}
+
+func TestSilence_RestoredWhenUnwinding(t *testing.T) {
+	vm := New()
+	frame := NewFrame(mainScript(nil))
+
+	vm.opBeginSilence(frame, Instruction{})
+	vm.opBeginSilence(frame, Instruction{})
+	if vm.ErrorReporting()&runtime.E_WARNING != 0 {
+		t.Fatalf("Warnings should be silenced")
+	}
+	if vm.ErrorReporting()&runtime.E_USER_ERROR == 0 {
+		t.Errorf("Fatal errors should stay reported")
+	}
+
+	vm.handleException(frame, vm.ThrowError("Exception", "boom"))
+	if vm.ErrorReporting() != runtime.E_ALL || len(frame.silenced) != 0 {
+		t.Errorf("Expected error_reporting restored, got %d", vm.ErrorReporting())
+	}
+}
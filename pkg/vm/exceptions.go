@@ -0,0 +1,610 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Throwable Propagation
+// ============================================================================
+
+// ThrowableError carries a thrown PHP Throwable through Go error returns
+// while the VM unwinds frames looking for a matching catch block
+type ThrowableError struct {
+	Object *types.Object
+}
+
+// Error returns the fatal error message PHP prints for an uncaught exception
+func (e *ThrowableError) Error() string {
+	return fmt.Sprintf("Uncaught %s\n  thrown in %s on line %d",
+		throwableString(e.Object),
+		throwableProperty(e.Object, "file").ToString(),
+		throwableProperty(e.Object, "line").ToInt())
+}
+
+// Class returns the name of the exception's class
+func (e *ThrowableError) Class() string {
+	return e.Object.ClassName
+}
+
+// Message returns the exception's message
+func (e *ThrowableError) Message() string {
+	return throwableProperty(e.Object, "message").ToString()
+}
+
+// Location returns the file and line the exception was created at
+func (e *ThrowableError) Location() (string, int) {
+	return throwableProperty(e.Object, "file").ToString(), int(throwableProperty(e.Object, "line").ToInt())
+}
+
+// Previous returns the exception's previous exception, or nil
+func (e *ThrowableError) Previous() *ThrowableError {
+	previous := throwableProperty(e.Object, "previous")
+	if !previous.IsObject() {
+		return nil
+	}
+	return &ThrowableError{Object: previous.ToObject()}
+}
+
+// NativeMethod is a method implemented in Go for a built-in class
+type NativeMethod func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error)
+
+// bind fixes $this, turning the method into a plain builtin function
+func (m NativeMethod) bind(this *types.Object) BuiltinFunction {
+	return func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return m(vm, this, args)
+	}
+}
+
+// fastCall records an entered finally block (see FAST_CALL / FAST_RET)
+type fastCall struct {
+	returnIP  int           // Instruction to resume at after the finally block (-1 when unwinding)
+	exception *types.Object // Exception to rethrow after the finally block
+}
+
+// SetScriptPath sets the file name reported in exceptions and errors
+func (vm *VM) SetScriptPath(path string) {
+	vm.scriptPath = path
+}
+
+// ThrowError creates an instance of a built-in Throwable class and returns
+// it as an error, ready to be returned from an opcode handler or builtin
+func (vm *VM) ThrowError(className string, format string, args ...interface{}) error {
+	class, ok := vm.classes[className]
+	if !ok {
+		class = vm.classes["Error"]
+	}
+	obj := types.NewObjectFromClass(class)
+	vm.initThrowable(obj)
+	setThrowableProperty(obj, "message", types.NewString(fmt.Sprintf(format, args...)))
+	return &ThrowableError{Object: obj}
+}
+
+// isThrowable reports whether a class implements Throwable
+func (vm *VM) isThrowable(class *types.ClassEntry) bool {
+	return vm.isInstanceOf(class, "Throwable")
+}
+
+// handleException looks for a catch or finally block of the frame able to
+// handle err, and moves the instruction pointer there. It returns false if
+// err is not a PHP exception or the frame has no handler for it.
+func (vm *VM) handleException(frame *Frame, err error) bool {
+	var thrown *ThrowableError
+	if !errors.As(err, &thrown) {
+		return false
+	}
+
+	// The exception leaves any @ expression of the frame, and abandons the
+	// calls it was preparing
+	vm.unwindSilence(frame)
+	frame.abandonPendingCalls()
+
+	opNum := frame.ip - 1
+	table := frame.fn.TryCatch
+
+	// Find the innermost region containing the throwing instruction
+	current := -1
+	for i, tc := range table {
+		if tc.TryOp > opNum {
+			break
+		}
+		if opNum < tc.CatchOp || opNum < tc.FinallyEnd {
+			current = i
+		}
+	}
+
+	for ; current >= 0; current-- {
+		tc := table[current]
+
+		// Thrown inside the try block: run the catch chain
+		if tc.CatchOp > 0 && opNum < tc.CatchOp {
+			frame.exception = thrown.Object
+			frame.ip = tc.CatchOp
+			return true
+		}
+
+		// Thrown inside try or catch: run finally, then rethrow
+		if tc.FinallyOp > 0 && opNum < tc.FinallyOp {
+			frame.fastCalls = append(frame.fastCalls, fastCall{returnIP: -1, exception: thrown.Object})
+			frame.ip = tc.FinallyOp
+			return true
+		}
+
+		// Thrown inside finally: the new exception replaces the pending one,
+		// which becomes its previous exception
+		if tc.FinallyOp > 0 && opNum < tc.FinallyEnd && len(frame.fastCalls) > 0 {
+			pending := frame.fastCalls[len(frame.fastCalls)-1]
+			frame.fastCalls = frame.fastCalls[:len(frame.fastCalls)-1]
+			if pending.exception != nil && pending.exception != thrown.Object {
+				chainPrevious(thrown.Object, pending.exception)
+			}
+		}
+	}
+
+	return false
+}
+
+// chainPrevious appends previous to the end of the exception's previous chain
+func chainPrevious(exception, previous *types.Object) {
+	for current := exception; ; {
+		next := throwableProperty(current, "previous")
+		if !next.IsObject() {
+			setThrowableProperty(current, "previous", types.NewObject(previous))
+			return
+		}
+		if next.ToObject() == previous {
+			return
+		}
+		current = next.ToObject()
+	}
+}
+
+// ============================================================================
+// Exception Opcode Handlers
+// ============================================================================
+
+// opThrow throws an exception
+// Op1: the Throwable object
+func (vm *VM) opThrow(frame *Frame, instr Instruction) error {
+	value, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+
+	value = value.Deref()
+	if !value.IsObject() || !vm.isThrowable(value.ToObject().ClassEntry) {
+		return vm.ThrowError("Error", "Can only throw objects")
+	}
+
+	return &ThrowableError{Object: value.ToObject()}
+}
+
+// opCatch matches the active exception against a catch clause
+// Op1: class name(s) to catch, separated by "|" for multi-catch
+// Op2: jump target of the next catch clause (used when the type does not match)
+// Result: variable receiving the exception (unused for catch without variable)
+// ExtendedValue: CatchLast if this is the last catch clause
+func (vm *VM) opCatch(frame *Frame, instr Instruction) error {
+	exception := frame.exception
+	if exception == nil {
+		return fmt.Errorf("CATCH: no active exception")
+	}
+
+	classNames, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+
+	matched := false
+	for _, name := range strings.Split(classNames.ToString(), "|") {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "\\")
+		if vm.isInstanceOf(exception.ClassEntry, name) {
+			matched = true
+			break
+		}
+	}
+
+	if !matched {
+		if instr.ExtendedValue&CatchLast != 0 {
+			frame.exception = nil
+			return &ThrowableError{Object: exception}
+		}
+		frame.ip = int(instr.Op2.Value)
+		return nil
+	}
+
+	frame.exception = nil
+	if instr.Result.Type != OpUnused {
+		return vm.setOperandValue(frame, instr.Result, types.NewObject(exception))
+	}
+	return nil
+}
+
+// CatchLast marks the last CATCH instruction of a try statement
+const CatchLast uint32 = 1
+
+// opFastCall enters a finally block on normal control flow
+// Op1: jump target (first instruction of the finally block)
+func (vm *VM) opFastCall(frame *Frame, instr Instruction) error {
+	frame.fastCalls = append(frame.fastCalls, fastCall{returnIP: frame.ip})
+	frame.ip = int(instr.Op1.Value)
+	return nil
+}
+
+// opFastRet leaves a finally block, either resuming after the FAST_CALL
+// that entered it or rethrowing the exception that caused it to run
+func (vm *VM) opFastRet(frame *Frame, instr Instruction) error {
+	if len(frame.fastCalls) == 0 {
+		return fmt.Errorf("FAST_RET: no active finally block")
+	}
+
+	call := frame.fastCalls[len(frame.fastCalls)-1]
+	frame.fastCalls = frame.fastCalls[:len(frame.fastCalls)-1]
+
+	if call.exception != nil {
+		return &ThrowableError{Object: call.exception}
+	}
+	frame.ip = call.returnIP
+	return nil
+}
+
+// opDiscardException leaves the innermost finally block early, by a
+// return, break or continue: it does not resume, and the exception it
+// would rethrow is dropped
+func (vm *VM) opDiscardException(frame *Frame, instr Instruction) error {
+	if len(frame.fastCalls) > 0 {
+		frame.fastCalls = frame.fastCalls[:len(frame.fastCalls)-1]
+	}
+	return nil
+}
+
+// ============================================================================
+// Throwable Objects
+// ============================================================================
+
+// initThrowable fills in the file, line and stack trace of a new exception
+// from the current execution point
+func (vm *VM) initThrowable(obj *types.Object) {
+	line := int64(vm.currentLine())
+
+	defaults := []struct {
+		name       string
+		value      *types.Value
+		visibility types.PropertyVisibility
+	}{
+		{"message", types.NewString(""), types.VisibilityProtected},
+		{"code", types.NewInt(0), types.VisibilityProtected},
+		{"file", types.NewString(vm.scriptPath), types.VisibilityProtected},
+		{"line", types.NewInt(line), types.VisibilityProtected},
+		{"trace", types.NewArray(vm.stackTrace()), types.VisibilityPrivate},
+		{"previous", types.NewNull(), types.VisibilityPrivate},
+	}
+
+	for _, def := range defaults {
+		prop, ok := obj.Properties[def.name]
+		if !ok {
+			prop = &types.Property{Visibility: def.visibility}
+			obj.AddProperty(def.name, prop)
+		}
+		// file, line and trace always reflect the creation point
+		if prop.Value == nil || def.name == "file" || def.name == "line" || def.name == "trace" {
+			prop.Value = def.value
+		}
+	}
+}
+
+// stackTrace builds the PHP backtrace of the current call stack, innermost
+// first. The self tail calls a frame ran (see reuseFrame) come before the
+// call of the frame, as they would with a frame each.
+func (vm *VM) stackTrace() *types.Array {
+	trace := types.NewEmptyArray()
+
+	for i := vm.frameIndex; i >= 1; i-- {
+		frame := vm.frames[i]
+		caller := vm.frames[i-1]
+
+		for j := len(frame.tailCalls) - 1; j >= 0; j-- {
+			for n := 0; n < frame.tailCalls[j].count; n++ {
+				trace.Append(types.NewArray(vm.traceEntry(frame, int64(frame.tailCalls[j].line))))
+			}
+		}
+		line := int64(0)
+		if caller.ip > 0 && caller.ip <= len(caller.fn.Instructions) {
+			line = int64(caller.fn.Instructions[caller.ip-1].Lineno)
+		}
+		trace.Append(types.NewArray(vm.traceEntry(frame, line)))
+	}
+
+	return trace
+}
+
+// traceEntry returns the backtrace entry of a call of a frame's function
+// made from a line
+func (vm *VM) traceEntry(frame *Frame, line int64) *types.Array {
+	entry := types.NewEmptyArray()
+	entry.Set(types.NewString("file"), types.NewString(vm.scriptPath))
+	entry.Set(types.NewString("line"), types.NewInt(line))
+	entry.Set(types.NewString("function"), types.NewString(frame.fn.Name))
+	if frame.currentClass != nil {
+		entry.Set(types.NewString("class"), types.NewString(frame.currentClass.Name))
+		if frame.thisObject != nil {
+			entry.Set(types.NewString("type"), types.NewString("->"))
+		} else {
+			entry.Set(types.NewString("type"), types.NewString("::"))
+		}
+	}
+	return entry
+}
+
+// throwableProperty reads an exception property, bypassing visibility
+func throwableProperty(obj *types.Object, name string) *types.Value {
+	if prop, ok := obj.Properties[name]; ok && prop.Value != nil {
+		return prop.Value
+	}
+	return types.NewNull()
+}
+
+// setThrowableProperty writes an exception property, bypassing visibility
+func setThrowableProperty(obj *types.Object, name string, value *types.Value) {
+	if prop, ok := obj.Properties[name]; ok {
+		prop.Value = value
+		return
+	}
+	obj.AddProperty(name, &types.Property{Value: value, Visibility: types.VisibilityProtected})
+}
+
+// traceAsString formats a trace array like Exception::getTraceAsString()
+func traceAsString(trace *types.Value) string {
+	var sb strings.Builder
+	index := 0
+
+	if trace.IsArray() {
+		trace.ToArray().Each(func(_, frame *types.Value) bool {
+			entry := frame.ToArray()
+			get := func(key string) string {
+				if v, ok := entry.Get(types.NewString(key)); ok {
+					return v.ToString()
+				}
+				return ""
+			}
+			fmt.Fprintf(&sb, "#%d %s(%s): %s%s%s()\n", index, get("file"), get("line"), get("class"), get("type"), get("function"))
+			index++
+			return true
+		})
+	}
+
+	fmt.Fprintf(&sb, "#%d {main}", index)
+	return sb.String()
+}
+
+// throwableString formats an exception and its previous chain like
+// Exception::__toString(): the innermost previous exception comes first
+func throwableString(obj *types.Object) string {
+	result := ""
+	for current := obj; current != nil; {
+		message := throwableProperty(current, "message").ToString()
+		header := current.ClassName
+		if message != "" {
+			header += ": " + message
+		}
+
+		str := fmt.Sprintf("%s in %s:%d\nStack trace:\n%s", header,
+			throwableProperty(current, "file").ToString(),
+			throwableProperty(current, "line").ToInt(),
+			traceAsString(throwableProperty(current, "trace")))
+		if result != "" {
+			str += "\n\nNext " + result
+		}
+		result = str
+
+		previous := throwableProperty(current, "previous")
+		if !previous.IsObject() {
+			break
+		}
+		current = previous.ToObject()
+	}
+	return result
+}
+
+// ============================================================================
+// Built-in Exception Classes
+// ============================================================================
+
+// builtinExceptionClasses lists the built-in Throwable classes and their parents,
+// in declaration order (parents before children)
+var builtinExceptionClasses = []struct{ name, parent string }{
+	{"Exception", ""},
+	{"Error", ""},
+	{"ErrorException", "Exception"},
+
+	// Engine errors
+	{"CompileError", "Error"},
+	{"ParseError", "CompileError"},
+	{"TypeError", "Error"},
+	{"ArgumentCountError", "TypeError"},
+	{"ValueError", "Error"},
+	{"ArithmeticError", "Error"},
+	{"DivisionByZeroError", "ArithmeticError"},
+	{"AssertionError", "Error"},
+	{"UnhandledMatchError", "Error"},
+
+	// SPL exceptions
+	{"LogicException", "Exception"},
+	{"BadFunctionCallException", "LogicException"},
+	{"BadMethodCallException", "BadFunctionCallException"},
+	{"DomainException", "LogicException"},
+	{"InvalidArgumentException", "LogicException"},
+	{"LengthException", "LogicException"},
+	{"OutOfRangeException", "LogicException"},
+	{"RuntimeException", "Exception"},
+	{"OutOfBoundsException", "RuntimeException"},
+	{"OverflowException", "RuntimeException"},
+	{"RangeException", "RuntimeException"},
+	{"UnderflowException", "RuntimeException"},
+	{"UnexpectedValueException", "RuntimeException"},
+	{"JsonException", "Exception"},
+	{"ReflectionException", "Exception"},
+
+	// Date exceptions
+	{"DateException", "Exception"},
+	{"DateMalformedStringException", "DateException"},
+	{"DateMalformedIntervalStringException", "DateException"},
+	{"DateInvalidTimeZoneException", "DateException"},
+}
+
+// registerExceptionClasses registers Throwable and the built-in exception hierarchy
+func (vm *VM) registerExceptionClasses() {
+	stringable := types.NewInterfaceEntry("Stringable")
+	throwable := types.NewInterfaceEntry("Throwable")
+	throwable.ParentInterfaces = append(throwable.ParentInterfaces, stringable)
+
+	for _, iface := range []*types.InterfaceEntry{stringable, throwable} {
+		vm.classes[iface.Name] = interfaceClass(iface)
+	}
+
+	for _, def := range builtinExceptionClasses {
+		class := types.NewClassEntry(def.name)
+
+		if def.parent == "" {
+			// Root classes (Exception, Error) declare the Throwable state and methods
+			class.Interfaces = append(class.Interfaces, throwable)
+			defineThrowableMembers(class)
+		} else {
+			class.InheritFrom(vm.classes[def.parent])
+			class.Constructor = class.ParentClass.Constructor
+			class.Methods["__construct"] = class.Constructor
+		}
+
+		if def.name == "ErrorException" {
+			class.Properties["severity"] = &types.PropertyDef{
+				Name: "severity", Visibility: types.VisibilityProtected,
+				HasDefault: true, Default: types.NewInt(1), DeclaringClass: def.name,
+			}
+			class.PropertyOrder = append(class.PropertyOrder, "severity")
+			addNativeMethod(class, "__construct", 6, errorExceptionConstruct)
+			class.Constructor = class.Methods["__construct"]
+			class.Constructor.IsConstructor = true
+			addNativeMethod(class, "getSeverity", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+				return throwableProperty(this, "severity"), nil
+			})
+		}
+
+		vm.classes[def.name] = class
+	}
+}
+
+// defineThrowableMembers adds the Throwable properties and methods to a root class
+func defineThrowableMembers(class *types.ClassEntry) {
+	props := []struct {
+		name       string
+		value      *types.Value
+		visibility types.PropertyVisibility
+	}{
+		{"message", types.NewString(""), types.VisibilityProtected},
+		{"code", types.NewInt(0), types.VisibilityProtected},
+		{"file", types.NewString(""), types.VisibilityProtected},
+		{"line", types.NewInt(0), types.VisibilityProtected},
+		{"trace", types.NewArray(types.NewEmptyArray()), types.VisibilityPrivate},
+		{"previous", types.NewNull(), types.VisibilityPrivate},
+	}
+	for _, p := range props {
+		class.Properties[p.name] = &types.PropertyDef{
+			Name:           p.name,
+			Visibility:     p.visibility,
+			HasDefault:     true,
+			Default:        p.value,
+			DeclaringClass: class.Name,
+		}
+		class.PropertyOrder = append(class.PropertyOrder, p.name)
+	}
+
+	addNativeMethod(class, "__construct", 3, throwableConstruct)
+	class.Constructor = class.Methods["__construct"]
+	class.Constructor.IsConstructor = true
+
+	getter := func(name string) NativeMethod {
+		return func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+			return throwableProperty(this, name), nil
+		}
+	}
+	for method, prop := range map[string]string{
+		"getMessage":  "message",
+		"getCode":     "code",
+		"getFile":     "file",
+		"getLine":     "line",
+		"getTrace":    "trace",
+		"getPrevious": "previous",
+	} {
+		addNativeMethod(class, method, 0, getter(prop)).IsFinal = true
+	}
+
+	addNativeMethod(class, "getTraceAsString", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return types.NewString(traceAsString(throwableProperty(this, "trace"))), nil
+	}).IsFinal = true
+
+	addNativeMethod(class, "__toString", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return types.NewString(throwableString(this)), nil
+	}).IsMagic = true
+}
+
+// addNativeMethod registers a Go-implemented public method on a class
+func addNativeMethod(class *types.ClassEntry, name string, numParams int, fn NativeMethod) *types.MethodDef {
+	method := &types.MethodDef{
+		Name:           name,
+		Visibility:     types.VisibilityPublic,
+		NumParams:      numParams,
+		DeclaringClass: class.Name,
+		Handler:        fn,
+	}
+	class.Methods[name] = method
+	return method
+}
+
+// Exception::__construct(string $message = "", int $code = 0, ?Throwable $previous = null)
+func throwableConstruct(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	if len(args) > 0 {
+		setThrowableProperty(this, "message", types.NewString(args[0].ToString()))
+	}
+	if len(args) > 1 {
+		setThrowableProperty(this, "code", types.NewInt(args[1].ToInt()))
+	}
+	if len(args) > 2 && !args[2].IsNull() {
+		previous := args[2].Deref()
+		if !previous.IsObject() || !vm.isThrowable(previous.ToObject().ClassEntry) {
+			return nil, vm.ThrowError("TypeError", "%s::__construct(): Argument #3 ($previous) must be of type ?Throwable, %s given",
+				this.ClassName, previous.TypeString())
+		}
+		setThrowableProperty(this, "previous", previous)
+	}
+	return types.NewNull(), nil
+}
+
+// ErrorException::__construct(string $message = "", int $code = 0, int $severity = E_ERROR,
+//
+//	?string $filename = null, ?int $line = null, ?Throwable $previous = null)
+func errorExceptionConstruct(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+	base := args
+	if len(base) > 2 {
+		base = base[:2]
+	}
+	if _, err := throwableConstruct(vm, this, base); err != nil {
+		return nil, err
+	}
+	if len(args) > 2 {
+		setThrowableProperty(this, "severity", types.NewInt(args[2].ToInt()))
+	}
+	if len(args) > 3 && !args[3].IsNull() {
+		setThrowableProperty(this, "file", types.NewString(args[3].ToString()))
+	}
+	if len(args) > 4 && !args[4].IsNull() {
+		setThrowableProperty(this, "line", types.NewInt(args[4].ToInt()))
+	}
+	if len(args) > 5 {
+		return throwableConstruct(vm, this, []*types.Value{types.NewString(""), types.NewInt(0), args[5]})
+	}
+	return types.NewNull(), nil
+}
@@ -0,0 +1,323 @@
+package vm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// runMain executes fn as the main frame and returns the VM error
+func runMain(vm *VM, fn *CompiledFunction) error {
+	frame := NewFrame(fn)
+	vm.pushFrame(frame)
+	return vm.runFrame(frame)
+}
+
+// newException emits: T0 = new <class>(<message>)
+// Expects constants[class] and constants[message] to be the class name and message
+func newException(class, message uint32) Instructions {
+	return Instructions{
+		{Opcode: OpNew, Op1: Operand{Type: OpConst, Value: class}, Result: Operand{Type: OpTmpVar, Value: 0}},
+		{Opcode: OpInitMethodCall, Op1: Operand{Type: OpTmpVar, Value: 0}, Op2: Operand{Type: OpConst, Value: 2}},
+		{Opcode: OpSendVal, Op1: Operand{Type: OpConst, Value: message}},
+		{Opcode: OpDoFcall},
+	}
+}
+
+func TestException_ThrowAndCatch(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"RuntimeException", "boom", "__construct", "LogicException", "Exception", "getMessage", "unreached"}
+
+	// try { throw new RuntimeException("boom"); echo "unreached"; }
+	// catch (LogicException $e) {} catch (Exception $e) { echo $e->getMessage(); }
+	instrs := newException(0, 1)
+	instrs = append(instrs,
+		Instruction{Opcode: OpThrow, Op1: Operand{Type: OpTmpVar, Value: 0}},                                                               // 4
+		Instruction{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 6}},                                                                 // 5
+		Instruction{Opcode: OpJmp, Op1: Operand{Value: 12}},                                                                                // 6
+		Instruction{Opcode: OpCatch, Op1: Operand{Type: OpConst, Value: 3}, Op2: Operand{Value: 8}, Result: Operand{Type: OpCV, Value: 0}}, // 7
+		Instruction{Opcode: OpCatch, Op1: Operand{Type: OpConst, Value: 4}, Result: Operand{Type: OpCV, Value: 0}, ExtendedValue: CatchLast},
+		Instruction{Opcode: OpInitMethodCall, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 5}},
+		Instruction{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 1}},
+		Instruction{Opcode: OpEcho, Op1: Operand{Type: OpTmpVar, Value: 1}},
+	)
+
+	fn := &CompiledFunction{
+		Name:         "main",
+		Instructions: instrs,
+		NumLocals:    10,
+		TryCatch:     []types.TryCatchElement{{TryOp: 0, CatchOp: 7}},
+	}
+
+	if err := runMain(vm, fn); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm.GetOutput() != "boom" {
+		t.Errorf("Expected output 'boom', got %q", vm.GetOutput())
+	}
+}
+
+func TestException_UnmatchedCatchRethrows(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"Error", "bad", "__construct", "Exception"}
+
+	// try { throw new Error("bad"); } catch (Exception $e) {}
+	instrs := newException(0, 1)
+	instrs = append(instrs,
+		Instruction{Opcode: OpThrow, Op1: Operand{Type: OpTmpVar, Value: 0}},
+		Instruction{Opcode: OpCatch, Op1: Operand{Type: OpConst, Value: 3}, Result: Operand{Type: OpCV, Value: 0}, ExtendedValue: CatchLast},
+	)
+
+	fn := &CompiledFunction{
+		Name:         "main",
+		Instructions: instrs,
+		NumLocals:    10,
+		TryCatch:     []types.TryCatchElement{{TryOp: 0, CatchOp: 5}},
+	}
+
+	err := runMain(vm, fn)
+	var thrown *ThrowableError
+	if !errors.As(err, &thrown) || thrown.Object.ClassName != "Error" {
+		t.Fatalf("Expected uncaught Error, got %v", err)
+	}
+}
+
+func TestException_PropagatesThroughCalls(t *testing.T) {
+	vm := New()
+	vm.SetScriptPath("/tmp/test.php")
+	vm.constants = []interface{}{"InvalidArgumentException", "nope", "__construct", "thrower", "Throwable", "caught"}
+
+	// function thrower() { throw new InvalidArgumentException("nope"); }
+	thrower := newException(0, 1)
+	thrower = append(thrower, Instruction{Opcode: OpThrow, Op1: Operand{Type: OpTmpVar, Value: 0}, Lineno: 3})
+	vm.RegisterFunction("thrower", &CompiledFunction{Name: "thrower", Instructions: thrower, NumLocals: 10})
+
+	// try { thrower(); } catch (Throwable $e) { echo "caught"; }
+	fn := &CompiledFunction{
+		Name: "main",
+		Instructions: Instructions{
+			{Opcode: OpInitFcall, Op2: Operand{Type: OpConst, Value: 3}},
+			{Opcode: OpDoFcall, Lineno: 7},
+			{Opcode: OpJmp, Op1: Operand{Value: 5}},
+			{Opcode: OpCatch, Op1: Operand{Type: OpConst, Value: 4}, Result: Operand{Type: OpCV, Value: 0}, ExtendedValue: CatchLast},
+			{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 5}},
+		},
+		NumLocals: 10,
+		TryCatch:  []types.TryCatchElement{{TryOp: 0, CatchOp: 3}},
+	}
+
+	if err := runMain(vm, fn); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm.GetOutput() != "caught" {
+		t.Errorf("Expected output 'caught', got %q", vm.GetOutput())
+	}
+	if vm.frameIndex != 0 {
+		t.Errorf("Callee frame should be unwound, frame index is %d", vm.frameIndex)
+	}
+
+	exception := vm.currentFrame().getLocal(0).ToObject()
+	trace := traceAsString(throwableProperty(exception, "trace"))
+	if trace != "#0 /tmp/test.php(7): thrower()\n#1 {main}" {
+		t.Errorf("Unexpected trace:\n%s", trace)
+	}
+}
+
+func TestException_FinallyRunsOnNormalAndExceptionalExit(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"Exception", "oops", "__construct", "body;", "finally;"}
+
+	// try { echo "body;"; } finally { echo "finally;"; }
+	normal := &CompiledFunction{
+		Name: "main",
+		Instructions: Instructions{
+			{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 3}},
+			{Opcode: OpFastCall, Op1: Operand{Value: 3}},
+			{Opcode: OpJmp, Op1: Operand{Value: 5}},
+			{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 4}},
+			{Opcode: OpFastRet},
+		},
+		NumLocals: 10,
+		TryCatch:  []types.TryCatchElement{{TryOp: 0, FinallyOp: 3, FinallyEnd: 4}},
+	}
+
+	if err := runMain(vm, normal); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm.GetOutput() != "body;finally;" {
+		t.Errorf("Expected 'body;finally;', got %q", vm.GetOutput())
+	}
+
+	// try { throw new Exception("oops"); } finally { echo "finally;"; }
+	vm = New()
+	vm.constants = []interface{}{"Exception", "oops", "__construct", "body;", "finally;"}
+	instrs := newException(0, 1)
+	instrs = append(instrs,
+		Instruction{Opcode: OpThrow, Op1: Operand{Type: OpTmpVar, Value: 0}}, // 4
+		Instruction{Opcode: OpFastCall, Op1: Operand{Value: 7}},              // 5
+		Instruction{Opcode: OpJmp, Op1: Operand{Value: 9}},                   // 6
+		Instruction{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 4}},   // 7
+		Instruction{Opcode: OpFastRet},                                       // 8
+	)
+	throwing := &CompiledFunction{
+		Name:         "main",
+		Instructions: instrs,
+		NumLocals:    10,
+		TryCatch:     []types.TryCatchElement{{TryOp: 0, FinallyOp: 7, FinallyEnd: 8}},
+	}
+
+	err := runMain(vm, throwing)
+	var thrown *ThrowableError
+	if !errors.As(err, &thrown) {
+		t.Fatalf("Expected exception to be rethrown after finally, got %v", err)
+	}
+	if vm.GetOutput() != "finally;" {
+		t.Errorf("Expected 'finally;', got %q", vm.GetOutput())
+	}
+}
+
+func TestException_DiscardLeavesFinally(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"Exception", "oops", "__construct", "inner;", "outer;"}
+
+	// while (...) { try { try { throw new Exception("oops"); }
+	// finally { echo "inner;"; break; } } finally { echo "outer;"; } }
+	instrs := newException(0, 1)
+	instrs = append(instrs,
+		Instruction{Opcode: OpThrow, Op1: Operand{Type: OpTmpVar, Value: 0}}, // 4
+		Instruction{Opcode: OpFastCall, Op1: Operand{Value: 7}},              // 5
+		Instruction{Opcode: OpJmp, Op1: Operand{Value: 12}},                  // 6
+		Instruction{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 3}},   // 7
+		Instruction{Opcode: OpDiscardException},                              // 8
+		Instruction{Opcode: OpFastCall, Op1: Operand{Value: 14}},             // 9
+		Instruction{Opcode: OpJmp, Op1: Operand{Value: 16}},                  // 10
+		Instruction{Opcode: OpFastRet},                                       // 11
+		Instruction{Opcode: OpFastCall, Op1: Operand{Value: 14}},             // 12
+		Instruction{Opcode: OpJmp, Op1: Operand{Value: 16}},                  // 13
+		Instruction{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 4}},   // 14
+		Instruction{Opcode: OpFastRet},                                       // 15
+	)
+	fn := &CompiledFunction{
+		Name:         "main",
+		Instructions: instrs,
+		NumLocals:    10,
+		TryCatch: []types.TryCatchElement{
+			{TryOp: 0, FinallyOp: 14, FinallyEnd: 15},
+			{TryOp: 0, FinallyOp: 7, FinallyEnd: 11},
+		},
+	}
+
+	frame := NewFrame(fn)
+	vm.pushFrame(frame)
+	if err := vm.runFrame(frame); err != nil {
+		t.Fatalf("Expected the exception to be discarded, got %v", err)
+	}
+	if vm.GetOutput() != "inner;outer;" {
+		t.Errorf("Expected 'inner;outer;', got %q", vm.GetOutput())
+	}
+	if len(frame.fastCalls) != 0 {
+		t.Errorf("Expected no finally block left running, got %d", len(frame.fastCalls))
+	}
+}
+
+func TestException_UncaughtMessage(t *testing.T) {
+	vm := New()
+	vm.SetScriptPath("/app/index.php")
+	vm.constants = []interface{}{"LogicException", "first", "__construct", "RuntimeException", "second"}
+
+	previous := types.NewObjectFromClass(vm.classes["LogicException"])
+	vm.initThrowable(previous)
+	setThrowableProperty(previous, "message", types.NewString("first"))
+	setThrowableProperty(previous, "line", types.NewInt(4))
+
+	obj := types.NewObjectFromClass(vm.classes["RuntimeException"])
+	vm.initThrowable(obj)
+	if _, err := throwableConstruct(vm, obj, []*types.Value{types.NewString("second"), types.NewInt(3), types.NewObject(previous)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	setThrowableProperty(obj, "line", types.NewInt(9))
+
+	expected := "Uncaught LogicException: first in /app/index.php:4\nStack trace:\n#0 {main}\n\n" +
+		"Next RuntimeException: second in /app/index.php:9\nStack trace:\n#0 {main}\n  thrown in /app/index.php on line 9"
+	if msg := (&ThrowableError{Object: obj}).Error(); msg != expected {
+		t.Errorf("Unexpected message:\n%s\nexpected:\n%s", msg, expected)
+	}
+	if throwableProperty(obj, "code").ToInt() != 3 {
+		t.Errorf("Expected code 3, got %v", throwableProperty(obj, "code"))
+	}
+}
+
+func TestException_Hierarchy(t *testing.T) {
+	vm := New()
+
+	tests := []struct {
+		class, ancestor string
+		expected        bool
+	}{
+		{"Exception", "Throwable", true},
+		{"Error", "Throwable", true},
+		{"Exception", "Stringable", true},
+		{"DivisionByZeroError", "ArithmeticError", true},
+		{"ArgumentCountError", "TypeError", true},
+		{"OutOfBoundsException", "RuntimeException", true},
+		{"BadMethodCallException", "LogicException", true},
+		{"TypeError", "Exception", false},
+		{"RuntimeException", "Error", false},
+	}
+
+	for _, tt := range tests {
+		class, ok := vm.classes[tt.class]
+		if !ok {
+			t.Errorf("Class %s is not registered", tt.class)
+			continue
+		}
+		if got := vm.isInstanceOf(class, tt.ancestor); got != tt.expected {
+			t.Errorf("%s instanceof %s: expected %v, got %v", tt.class, tt.ancestor, tt.expected, got)
+		}
+	}
+}
+
+func TestException_DivisionByZeroIsCatchable(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{int64(1), int64(0), "DivisionByZeroError", "getMessage"}
+
+	// try { 1 / 0; } catch (DivisionByZeroError $e) { echo $e->getMessage(); }
+	fn := &CompiledFunction{
+		Name: "main",
+		Instructions: Instructions{
+			{Opcode: OpDiv, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 0}},
+			{Opcode: OpJmp, Op1: Operand{Value: 6}},
+			{Opcode: OpCatch, Op1: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpCV, Value: 0}, ExtendedValue: CatchLast},
+			{Opcode: OpInitMethodCall, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 3}},
+			{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 1}},
+			{Opcode: OpEcho, Op1: Operand{Type: OpTmpVar, Value: 1}},
+		},
+		NumLocals: 10,
+		TryCatch:  []types.TryCatchElement{{TryOp: 0, CatchOp: 2}},
+	}
+
+	if err := runMain(vm, fn); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm.GetOutput() != "Division by zero" {
+		t.Errorf("Expected 'Division by zero', got %q", vm.GetOutput())
+	}
+}
+
+func TestException_ThrowNonObject(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"string"}
+
+	fn := &CompiledFunction{
+		Name:         "main",
+		Instructions: Instructions{{Opcode: OpThrow, Op1: Operand{Type: OpConst, Value: 0}}},
+		NumLocals:    10,
+	}
+
+	err := runMain(vm, fn)
+	if err == nil || !strings.Contains(err.Error(), "Uncaught Error: Can only throw objects") {
+		t.Errorf("Expected 'Can only throw objects' error, got %v", err)
+	}
+}
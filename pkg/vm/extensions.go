@@ -0,0 +1,296 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Go Extensions
+// ============================================================================
+
+// Extension is a PHP extension written in Go. Registered extensions are
+// loaded into every VM New creates afterwards, as PHP loads the
+// extensions built into its binary. Package goext builds extensions from
+// declarations of functions, classes, constants and ini directives.
+type Extension struct {
+	Name    string
+	Version string
+	Load    func(vm *VM) error // Declares the members of the extension in a VM
+}
+
+// builtinExtensions are the extensions implemented by the engine itself,
+// as get_loaded_extensions() lists them
+var builtinExtensions = []string{
+	"bcmath", "Core", "ctype", "curl", "date", "filter", "hash", "json", "mbstring",
+	"pcre", "PDO", "Reflection", "session", "SPL", "standard",
+}
+
+// Registered Go extensions, in registration order
+var (
+	extensionsMu sync.RWMutex
+	extensions   []*Extension
+)
+
+// RegisterExtension registers a Go extension for the VMs created
+// afterwards. The extension is loaded into a new VM first, so an
+// extension that fails to load, for example one extending a class that
+// does not exist, is reported here rather than by every VM.
+func RegisterExtension(ext *Extension) error {
+	if ext.Name == "" {
+		return fmt.Errorf("extension without a name")
+	}
+	if isExtensionName(ext.Name) {
+		return fmt.Errorf("extension %s is already registered", ext.Name)
+	}
+
+	if ext.Load != nil {
+		if err := ext.Load(New()); err != nil {
+			return fmt.Errorf("extension %s: %w", ext.Name, err)
+		}
+	}
+
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	extensions = append(extensions, ext)
+	return nil
+}
+
+// isExtensionName reports whether an extension of a name, built-in or
+// registered, exists
+func isExtensionName(name string) bool {
+	for _, builtin := range builtinExtensions {
+		if strings.EqualFold(builtin, name) {
+			return true
+		}
+	}
+	extensionsMu.RLock()
+	defer extensionsMu.RUnlock()
+	for _, ext := range extensions {
+		if strings.EqualFold(ext.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadExtensions loads the registered Go extensions into a new VM. They
+// were loaded once when registered, so loading them again does not fail.
+func (vm *VM) loadExtensions() {
+	extensionsMu.RLock()
+	registered := append([]*Extension(nil), extensions...)
+	extensionsMu.RUnlock()
+
+	for _, ext := range registered {
+		if ext.Load != nil {
+			ext.Load(vm)
+		}
+		vm.extensions = append(vm.extensions, ext)
+	}
+}
+
+// loadedExtension finds a loaded Go extension by name (case-insensitive)
+func (vm *VM) loadedExtension(name string) *Extension {
+	for _, ext := range vm.extensions {
+		if strings.EqualFold(ext.Name, name) {
+			return ext
+		}
+	}
+	return nil
+}
+
+// ============================================================================
+// Declared Builtins
+// ============================================================================
+
+// builtinSignature holds the declared parameters and return type of a Go
+// function
+type builtinSignature struct {
+	params     []*types.ParameterDef
+	returnType string
+	core       bool // Declared by coreSignatures
+}
+
+// DeclareBuiltin registers a Go function with its parameters and return
+// type declared, as PHP declares those of internal functions. Calls are
+// checked against the parameters: the number of arguments, then their
+// types, coerced in the caller's typing mode. Omitted optional arguments
+// are passed as their defaults. Named arguments bind to the parameters
+// and reflection reports them.
+func (vm *VM) DeclareBuiltin(name string, fn BuiltinFunction, params []*types.ParameterDef, returnType string) {
+	vm.RegisterBuiltin(name, fn)
+	if params == nil {
+		params = []*types.ParameterDef{}
+	}
+	vm.signatures[strings.ToLower(name)] = &builtinSignature{params: params, returnType: returnType}
+}
+
+// NewObject creates an object of a class and calls its constructor with
+// the arguments, as new does
+func (vm *VM) NewObject(className string, args ...*types.Value) (*types.Value, error) {
+	class, err := vm.linkedClass(className)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := vm.instantiate(class, &CallParams{params: args})
+	if err != nil {
+		return nil, err
+	}
+	return types.NewObject(obj), nil
+}
+
+// checkBuiltinArguments checks the arguments of a call to a Go function
+// or method against its declared parameters, returning the arguments to
+// pass: coerced to the parameter types, with omitted optional ones
+// filled with their defaults
+func (vm *VM) checkBuiltinArguments(name string, params []*types.ParameterDef, args []*types.Value, strict bool) ([]*types.Value, error) {
+	required, variadic := 0, false
+	for i, param := range params {
+		switch {
+		case param.IsVariadic:
+			variadic = true
+		case !param.HasDefault:
+			required = i + 1
+		}
+	}
+	maximum := len(params)
+	if variadic {
+		maximum--
+	}
+	if len(args) < required || !variadic && len(args) > maximum {
+		expected, bound := required, "at least"
+		if len(args) > required {
+			expected, bound = maximum, "at most"
+		}
+		if required == maximum && !variadic {
+			bound = "exactly"
+		}
+		plural := "s"
+		if expected == 1 {
+			plural = ""
+		}
+		return nil, vm.ThrowError("ArgumentCountError", "%s() expects %s %d argument%s, %d given",
+			name, bound, expected, plural, len(args))
+	}
+
+	checked := make([]*types.Value, len(args), max(len(args), maximum))
+	copy(checked, args)
+	for i := len(args); i < maximum; i++ {
+		checked = append(checked, types.NewNull())
+		if params[i].Default != nil {
+			checked[i] = assignValue(params[i].Default)
+		}
+	}
+
+	for i, arg := range checked[:len(args)] {
+		param := params[min(i, len(params)-1)]
+		if param.Type == "" || param.PassedByRef {
+			continue
+		}
+		if arg.Deref().IsNull() && param.HasDefault && param.Default != nil && param.Default.IsNull() {
+			continue // T $x = null is implicitly nullable
+		}
+		if arg.Deref().IsNull() && !strict && vm.takesNullScalars(name) && nullToScalar(param.Type) {
+			// Core functions still take null for a scalar, as "", 0 or false
+			vm.deprecated("%s(): Passing null to parameter #%d ($%s) of type %s is deprecated", name, i+1, param.Name, param.Type)
+			arg = types.NewBool(false)
+		}
+		value, ok, err := vm.coerceType(param.Type, arg, strict, typeScope{})
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, vm.ThrowError("TypeError", "%s(): Argument #%d ($%s) must be of type %s, %s given",
+				name, i+1, param.Name, param.Type, arg.TypeName())
+		}
+		if value != arg.Deref() {
+			checked[i] = value
+		}
+	}
+	return checked, nil
+}
+
+// takesNullScalars reports whether a function is a core function, which
+// takes null for its scalar parameters in coercive mode (deprecated since
+// PHP 8.1); declared builtins of extensions reject it
+func (vm *VM) takesNullScalars(name string) bool {
+	signature, ok := vm.signatures[strings.ToLower(name)]
+	return ok && signature.core
+}
+
+// nullToScalar reports whether a parameter type takes null as a scalar
+// in coercive mode: it is scalar and not nullable
+func nullToScalar(decl string) bool {
+	info := types.ParseType(decl)
+	if info.Accepts("null") {
+		return false
+	}
+	for _, scalar := range []string{"int", "float", "string", "bool"} {
+		if info.Accepts(scalar) {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================================================
+// Extension Builtins
+// ============================================================================
+
+// registerExtensionBuiltins registers the functions describing the loaded
+// extensions
+func (vm *VM) registerExtensionBuiltins() {
+	vm.RegisterBuiltin("extension_loaded", builtinExtensionLoaded)
+	vm.RegisterBuiltin("get_loaded_extensions", builtinGetLoadedExtensions)
+	vm.RegisterBuiltin("phpversion", builtinPhpversion)
+}
+
+// extension_loaded(string $extension): bool
+func builtinExtensionLoaded(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("extension_loaded() expects exactly 1 argument, %d given", len(args))
+	}
+	name := args[0].Deref().ToString()
+	for _, builtin := range builtinExtensions {
+		if strings.EqualFold(builtin, name) {
+			return types.NewBool(true), nil
+		}
+	}
+	return types.NewBool(vm.loadedExtension(name) != nil), nil
+}
+
+// get_loaded_extensions(bool $zend_extensions = false): array
+func builtinGetLoadedExtensions(vm *VM, args []*types.Value) (*types.Value, error) {
+	list := types.NewEmptyArray()
+	if len(args) > 0 && args[0].Deref().ToBool() {
+		return types.NewArray(list), nil
+	}
+	for _, name := range builtinExtensions {
+		list.Append(types.NewString(name))
+	}
+	for _, ext := range vm.extensions {
+		list.Append(types.NewString(ext.Name))
+	}
+	return types.NewArray(list), nil
+}
+
+// phpversion(?string $extension = null): string|false
+func builtinPhpversion(vm *VM, args []*types.Value) (*types.Value, error) {
+	version, _ := vm.LookupConstant("PHP_VERSION")
+	if len(args) == 0 || args[0].Deref().IsNull() {
+		return version, nil
+	}
+	name := args[0].Deref().ToString()
+	for _, builtin := range builtinExtensions {
+		if strings.EqualFold(builtin, name) {
+			return version, nil
+		}
+	}
+	if ext := vm.loadedExtension(name); ext != nil && ext.Version != "" {
+		return types.NewString(ext.Version), nil
+	}
+	return types.NewBool(false), nil
+}
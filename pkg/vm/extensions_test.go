@@ -0,0 +1,107 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestDeclareBuiltin(t *testing.T) {
+	vm := New()
+	vm.DeclareBuiltin("pad", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return types.NewString(args[0].ToString() + "|" + args[1].ToString() + "|" + args[2].ToString()), nil
+	}, []*types.ParameterDef{
+		{Name: "text", Type: "string"},
+		{Name: "width", Type: "int", HasDefault: true, Default: types.NewInt(10)},
+		{Name: "fill", Type: "string", HasDefault: true, Default: types.NewString(" ")},
+	}, "string")
+
+	// Named arguments bind to the declared parameters, skipped ones take
+	// their defaults and scalars are coerced
+	params := &CallParams{params: []*types.Value{types.NewInt(7)}}
+	if err := vm.addNamedArgument(params, "fill", types.NewString("*")); err != nil {
+		t.Fatal(err)
+	}
+	target, _ := vm.lookupFunction("pad")
+	args, _, err := vm.bindArguments(target.Name, target.parameters(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := vm.invokeTarget(target, args); err != nil || result.ToString() != "7|10|*" {
+		t.Errorf("pad(7, fill: \"*\") = %v, %v", result, err)
+	}
+
+	errorTests := []struct {
+		args    []*types.Value
+		strict  bool
+		class   string
+		message string
+	}{
+		{nil, false, "ArgumentCountError", "pad() expects at least 1 argument, 0 given"},
+		{[]*types.Value{types.NewString("a"), types.NewInt(1), types.NewString("-"), types.NewNull()}, false,
+			"ArgumentCountError", "pad() expects at most 3 arguments, 4 given"},
+		{[]*types.Value{types.NewString("a"), types.NewString("wide")}, false,
+			"TypeError", "pad(): Argument #2 ($width) must be of type int, string given"},
+		{[]*types.Value{types.NewInt(1)}, true, "TypeError", "pad(): Argument #1 ($text) must be of type string, int given"},
+	}
+	for _, tt := range errorTests {
+		target, _ := vm.lookupFunction("pad")
+		target.Strict = tt.strict
+		_, err := vm.invokeTarget(target, tt.args)
+		thrown, ok := err.(*ThrowableError)
+		if !ok || thrown.Object.ClassEntry.Name != tt.class || throwableProperty(thrown.Object, "message").ToString() != tt.message {
+			t.Errorf("Expected %s %q, got %v", tt.class, tt.message, err)
+		}
+	}
+}
+
+func TestCoreSignatures(t *testing.T) {
+	vm := New()
+	for _, declaration := range coreSignatures {
+		name, signature := vm.parseSignature(declaration)
+		if _, ok := vm.builtins[name]; !ok {
+			t.Errorf("%s() is declared but not registered", name)
+		}
+		if !signature.core || len(signature.params) == 0 && name != "pi" && !strings.HasSuffix(name, "getrandmax") {
+			t.Errorf("%s() parsed as %+v", name, signature)
+		}
+	}
+
+	_, format := vm.parseSignature(`number_format(float $num, int $decimals = 0, ?string $decimal_separator = ".", ?string $thousands_separator = ","): string`)
+	if len(format.params) != 4 || format.params[3].Type != "?string" || format.params[3].Default.ToString() != "," {
+		t.Errorf("number_format() parsed as %+v", format.params)
+	}
+	_, printf := vm.parseSignature("printf(string $format, mixed ...$values): int")
+	if len(printf.params) != 2 || !printf.params[1].IsVariadic || printf.params[1].Type != "mixed" || printf.returnType != "int" {
+		t.Errorf("printf() parsed as %+v", printf.params)
+	}
+
+	// Null passes for a scalar of a core function only in coercive mode
+	target, _ := vm.lookupFunction("strlen")
+	if result, err := vm.invokeTarget(target, []*types.Value{types.NewNull()}); err != nil || result.ToInt() != 0 {
+		t.Errorf("strlen(null) = %v, %v", result, err)
+	}
+	target.Strict = true
+	if _, err := vm.invokeTarget(target, []*types.Value{types.NewNull()}); err == nil {
+		t.Error("strlen(null) in strict mode did not throw")
+	}
+}
+
+func TestExtensionBuiltins(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+
+	if !call("extension_loaded", types.NewString("json")).ToBool() {
+		t.Error("extension_loaded(\"json\") = false")
+	}
+	if call("extension_loaded", types.NewString("xdebug")).ToBool() {
+		t.Error("extension_loaded(\"xdebug\") = true")
+	}
+	if list := call("get_loaded_extensions"); !list.IsArray() || list.ToArray().Len() < len(builtinExtensions) {
+		t.Errorf("get_loaded_extensions() = %v", list)
+	}
+	if version := call("phpversion"); version.ToString() != "8.4.0-dev" {
+		t.Errorf("phpversion() = %v", version)
+	}
+}
@@ -0,0 +1,158 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Foreach Loops
+// ============================================================================
+
+// foreachIterator walks the elements of a foreach loop. A loop by value
+// walks a copy of the array, so changes the body makes are not seen. A
+// loop by reference walks the keys its variable's array had when the loop
+// started, binding each element still present.
+type foreachIterator struct {
+	array *types.Array // Elements walked by value
+	pos   int          // Position of the next element of array
+
+	variable Operand        // Variable holding the array walked by reference
+	keys     []*types.Value // Keys left to walk by reference
+}
+
+// iterator returns the iterator of the foreach loop named by a temporary
+func (f *Frame) iterator(op Operand) (*foreachIterator, error) {
+	it, ok := f.iterators[op.Value]
+	if !ok {
+		return nil, fmt.Errorf("no foreach iterator in T%d", op.Value)
+	}
+	return it, nil
+}
+
+// foreachKeyOperand returns the temporary receiving the keys of a loop,
+// the one after the temporary receiving its values
+func foreachKeyOperand(value Operand) Operand {
+	return TmpVarOperand(value.Value + 1)
+}
+
+// foreachArray returns the elements a loop by value walks: a copy of an
+// array, or the public properties of an object. Other values warn and
+// have no elements.
+func (vm *VM) foreachArray(value *types.Value) *types.Array {
+	switch value.Type() {
+	case types.TypeArray:
+		return value.ToArray().Copy()
+	case types.TypeObject:
+		properties := types.NewEmptyArray()
+		for _, prop := range value.ToObject().DebugProperties() {
+			if prop.Visibility == types.VisibilityPublic {
+				properties.Set(prop.Key, prop.Value.Deref())
+			}
+		}
+		return properties
+	}
+	vm.warning("foreach() argument must be of type array|object, %s given", value.TypeName())
+	return types.NewEmptyArray()
+}
+
+// opFeResetR starts a foreach loop by value
+// Op1: the value walked, Result: the iterator
+func (vm *VM) opFeResetR(frame *Frame, instr Instruction) error {
+	value, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	if frame.iterators == nil {
+		frame.iterators = make(map[uint32]*foreachIterator)
+	}
+	frame.iterators[instr.Result.Value] = &foreachIterator{array: vm.foreachArray(value.Deref())}
+	return nil
+}
+
+// opFeResetRW starts a foreach loop by reference. Arrays are walked in
+// place; objects are walked like in a loop by value.
+// Op1: the variable walked, Result: the iterator
+func (vm *VM) opFeResetRW(frame *Frame, instr Instruction) error {
+	value, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	it := &foreachIterator{array: types.NewEmptyArray(), variable: instr.Op1}
+	if value := value.Deref(); value.IsArray() {
+		value.ToArray().Each(func(key, _ *types.Value) bool {
+			it.keys = append(it.keys, key)
+			return true
+		})
+	} else {
+		it.array = vm.foreachArray(value)
+	}
+	if frame.iterators == nil {
+		frame.iterators = make(map[uint32]*foreachIterator)
+	}
+	frame.iterators[instr.Result.Value] = it
+	return nil
+}
+
+// opFeFetchR fetches the next element of a foreach loop by value, or
+// jumps past the loop once there is none
+// Op1: iterator, Op2: jump target, Result: the value; the key is stored in
+// the temporary after it
+func (vm *VM) opFeFetchR(frame *Frame, instr Instruction) error {
+	it, err := frame.iterator(instr.Op1)
+	if err != nil {
+		return fmt.Errorf("FE_FETCH_R: %v", err)
+	}
+	key, value, next, ok := it.array.EntryAt(it.pos)
+	if !ok {
+		frame.ip = int(instr.Op2.Value)
+		return nil
+	}
+	it.pos = next
+	if err := vm.setOperandValue(frame, instr.Result, assignValue(value.Deref())); err != nil {
+		return err
+	}
+	return vm.setOperandValue(frame, foreachKeyOperand(instr.Result), key)
+}
+
+// opFeFetchRW fetches the next element of a foreach loop by reference as
+// a reference, or jumps past the loop once there is none. Elements removed
+// by the body are skipped.
+// Op1: iterator, Op2: jump target, Result: the reference; the key is
+// stored in the temporary after it
+func (vm *VM) opFeFetchRW(frame *Frame, instr Instruction) error {
+	it, err := frame.iterator(instr.Op1)
+	if err != nil {
+		return fmt.Errorf("FE_FETCH_RW: %v", err)
+	}
+	if it.keys == nil {
+		return vm.opFeFetchR(frame, instr)
+	}
+
+	container, err := vm.getOperandValue(frame, it.variable)
+	if err != nil {
+		return err
+	}
+	for len(it.keys) > 0 {
+		key := it.keys[0]
+		it.keys = it.keys[1:]
+		if container := container.Deref(); !container.IsArray() || !container.ToArray().HasKey(key) {
+			continue
+		}
+		element := elementReference(container.Deref().ToArray(), key)
+		if err := vm.setOperandValue(frame, instr.Result, element); err != nil {
+			return err
+		}
+		return vm.setOperandValue(frame, foreachKeyOperand(instr.Result), key)
+	}
+	frame.ip = int(instr.Op2.Value)
+	return nil
+}
+
+// opFeFree ends a foreach loop, dropping its iterator
+// Op1: iterator
+func (vm *VM) opFeFree(frame *Frame, instr Instruction) error {
+	delete(frame.iterators, instr.Op1.Value)
+	return nil
+}
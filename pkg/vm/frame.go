@@ -1,10 +1,14 @@
 package vm
 
-import "github.com/krizos/php-go/pkg/types"
+import (
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/types"
+)
 
 // CallParams holds parameters being collected for a function call
 type CallParams struct {
 	params []*types.Value
+	named  []namedArg // Arguments passed by name, in call order
 }
 
 // Frame represents a single execution frame (function call)
@@ -18,6 +22,9 @@ type Frame struct {
 	// Local variables (including parameters and temporaries)
 	locals []*types.Value
 
+	// Constants of the function converted to values, set on first use
+	constants *constantValues
+
 	// Return value (set by return statement)
 	returnValue *types.Value
 
@@ -29,13 +36,68 @@ type Frame struct {
 	currentClass  *types.ClassEntry // Current class context for self/parent
 	calledClass   *types.ClassEntry // Called class for late static binding (static::)
 
+	// Whether the compiled variables are bound to the global symbol table
+	globalScope bool
+
+	// Variables created by name ($$name) that are not compiled variables
+	// of the function, outside the global scope
+	namedVars map[string]*types.Value
+
+	// Static variables visible to this call (nil: the function's own,
+	// looked up on first use)
+	staticVars map[string]*types.Value
+
 	// Pending method call information (set by OpInitMethodCall)
 	pendingMethod *types.MethodDef // Method to be called
 	pendingObject *types.Object    // Object for instance method calls (nil for static)
+	pendingClass  *types.ClassEntry // Class for static method calls
+
+	// Called class of a pending static call; self:: and parent:: forward
+	// the caller's called class
+	pendingCalledClass *types.ClassEntry
+
+	// Pending call resolved from a callable value (set by OpInitFcallByName)
+	pendingCall *callTarget
 
 	// Pending function call information (set by OpInitFcall)
 	pendingFunction *CompiledFunction // Function to be called
 	pendingParams   *CallParams       // Parameters being collected
+
+	// Calls set aside while a call nested in their arguments is prepared,
+	// innermost last: f(g($x)) initializes g while f collects arguments
+	suspendedCalls []suspendedCall
+
+	// Iterators of the foreach loops running, by the temporary naming them
+	iterators map[uint32]*foreachIterator
+
+	// Arguments passed to the call, received by RECV instructions, and
+	// whether the caller was compiled with strict_types=1
+	args       []*types.Value
+	strictArgs bool
+
+	// Named arguments matching no parameter, collected by the variadic one
+	namedRest []namedArg
+
+	// Self tail calls run in this frame (see reuseFrame), innermost last,
+	// which backtraces show as calls of their own
+	tailCalls []tailCall
+
+	// Argument list the arguments came in, returned to the pool with the
+	// frame (see newCallParams)
+	callParams *CallParams
+
+	// Exception handling state
+	exception *types.Object // Exception being matched by CATCH
+	fastCalls []fastCall    // Finally blocks currently executing
+
+	// error_reporting levels saved by BEGIN_SILENCE, innermost last
+	silenced []runtime.ErrorType
+}
+
+// tailCall counts the self tail calls a frame ran from one line in a row
+type tailCall struct {
+	line  uint32
+	count int
 }
 
 // NewFrame creates a new execution frame for a function
@@ -55,6 +117,129 @@ func NewFrame(fn *CompiledFunction) *Frame {
 	}
 }
 
+// maxFreeFrames bounds the frames of finished calls a VM keeps
+const maxFreeFrames = 64
+
+// newCallFrame creates the frame of a function call like NewFrame, reusing
+// the frame of a finished call when its local slots are large enough.
+// Calls in a loop then allocate neither frames nor slots.
+func (vm *VM) newCallFrame(fn *CompiledFunction) *Frame {
+	last := len(vm.freeFrames) - 1
+	if last < 0 || cap(vm.freeFrames[last].locals) < max(fn.NumLocals, 10) {
+		return NewFrame(fn)
+	}
+	frame := vm.freeFrames[last]
+	vm.freeFrames[last] = nil
+	vm.freeFrames = vm.freeFrames[:last]
+
+	locals := frame.locals[:max(fn.NumLocals, 10)]
+	*frame = Frame{
+		fn:          fn,
+		locals:      locals,
+		returnValue: types.NewNull(),
+	}
+	return frame
+}
+
+// recycleFrame keeps the frame of a released call for newCallFrame. Once
+// the call returned nothing refers to the frame or its slots: closures and
+// references hold the values, not the slots. The frame is reset when it is
+// reused, so its return value can still be read.
+func (vm *VM) recycleFrame(frame *Frame) {
+	vm.recycleCallParams(frame.callParams)
+	frame.callParams = nil
+	if len(vm.freeFrames) < maxFreeFrames {
+		clear(frame.locals[:cap(frame.locals)])
+		vm.freeFrames = append(vm.freeFrames, frame)
+	}
+}
+
+// newCallParams returns an empty argument list for a call of n
+// arguments, reusing the list of a finished call
+func (vm *VM) newCallParams(n int) *CallParams {
+	last := len(vm.freeParams) - 1
+	if last < 0 {
+		return &CallParams{params: make([]*types.Value, 0, n)}
+	}
+	params := vm.freeParams[last]
+	vm.freeParams[last] = nil
+	vm.freeParams = vm.freeParams[:last]
+	return params
+}
+
+// recycleCallParams keeps the argument list of a finished call of user
+// code for newCallParams. The arguments of builtins are not recycled: a
+// builtin may keep them, as register_tick_function() does.
+func (vm *VM) recycleCallParams(params *CallParams) {
+	if params == nil || len(vm.freeParams) >= maxFreeFrames {
+		return
+	}
+	clear(params.params)
+	clear(params.named)
+	params.params, params.named = params.params[:0], params.named[:0]
+	vm.freeParams = append(vm.freeParams, params)
+}
+
+// canReuseFrame reports whether a call of fn can run in the frame making
+// it (see reuseFrame): fn must be the frame's own function, not a method,
+// and no debugger or function profiler may be attached, as they show
+// every call
+func (vm *VM) canReuseFrame(frame *Frame, fn *CompiledFunction, this *types.Object) bool {
+	return fn == frame.fn && this == nil && frame.thisObject == nil && frame.currentClass == nil &&
+		vm.debugger == nil && vm.callProfiler == nil
+}
+
+// reuseFrame runs a self tail call (see CallTail) in the frame making it:
+// the locals are released and the arguments bound as for a new call, and
+// execution restarts at the first instruction. Tail recursion then runs
+// without reaching the nesting limit, in constant space as long as the
+// calls come from one line: the calls are counted by line, so backtraces
+// still show each of them.
+func (vm *VM) reuseFrame(frame *Frame, params *CallParams, args []*types.Value, rest []namedArg) {
+	if frame.ip > 0 && frame.ip <= len(frame.fn.Instructions) {
+		frame.addTailCall(frame.fn.Instructions[frame.ip-1].Lineno)
+	}
+
+	// Binding a slot first adds the argument's holder, so an argument
+	// held by a slot released later stays alive
+	for i, local := range frame.locals {
+		if i < len(args) && i < frame.fn.NumParams {
+			frame.setParam(i, args[i])
+			continue
+		}
+		types.DelRef(local)
+		frame.locals[i] = nil
+	}
+	vm.recycleCallParams(frame.callParams)
+	frame.callParams = params
+	frame.args = args
+	frame.namedRest = rest
+	frame.namedVars = nil
+	frame.iterators = nil
+	frame.ip = 0
+}
+
+// addTailCall records a self tail call made from a line
+func (f *Frame) addTailCall(line uint32) {
+	if last := len(f.tailCalls) - 1; last >= 0 && f.tailCalls[last].line == line {
+		f.tailCalls[last].count++
+		return
+	}
+	f.tailCalls = append(f.tailCalls, tailCall{line: line, count: 1})
+}
+
+// functionName returns the name of the executing function as error
+// messages show it: Class::method for methods, {closure} for closures
+func (f *Frame) functionName() string {
+	if f.fn.Name == "<closure>" || f.fn.Name == "{closure}" {
+		return "{closure}"
+	}
+	if f.currentClass != nil {
+		return f.currentClass.Name + "::" + f.fn.Name
+	}
+	return f.fn.Name
+}
+
 // ============================================================================
 // Local Variable Access
 // ============================================================================
@@ -90,6 +275,8 @@ func (f *Frame) setLocal(index int, value *types.Value) {
 		f.locals = newLocals
 	}
 
+	types.AddRef(value)
+	types.DelRef(f.locals[index])
 	f.locals[index] = value
 }
 
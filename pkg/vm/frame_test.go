@@ -215,3 +215,103 @@ func TestFrame_String_Nil(t *testing.T) {
 		t.Errorf("Expected '<nil frame>', got '%s'", str)
 	}
 }
+
+func TestNewCallFrame_ReusesFrames(t *testing.T) {
+	vm := New()
+	small := &CompiledFunction{Name: "small", NumLocals: 4}
+	large := &CompiledFunction{Name: "large", NumLocals: 40}
+
+	frame := vm.newCallFrame(small)
+	frame.setLocal(0, types.NewString("left over"))
+	frame.setReturnValue(types.NewInt(7))
+	vm.releaseFrame(frame)
+	if frame.getReturnValue().ToInt() != 7 {
+		t.Error("A released frame should keep its return value until reused")
+	}
+
+	reused := vm.newCallFrame(small)
+	if reused != frame {
+		t.Fatal("A call frame should reuse a released frame")
+	}
+	if reused.locals[0] != nil || !reused.getReturnValue().IsNull() {
+		t.Error("A reused frame should be reset")
+	}
+	vm.releaseFrame(reused)
+
+	if grown := vm.newCallFrame(large); grown == frame || len(grown.locals) < 40 {
+		t.Error("A frame with too few local slots should not be reused")
+	}
+}
+
+func TestNewCallParams_ReusesLists(t *testing.T) {
+	vm := New()
+
+	params := vm.newCallParams(2)
+	params.params = append(params.params, types.NewInt(1), types.NewInt(2))
+	params.named = append(params.named, namedArg{name: "x", value: types.NewInt(3)})
+	vm.recycleCallParams(params)
+
+	reused := vm.newCallParams(2)
+	if reused != params {
+		t.Fatal("An argument list should be reused once recycled")
+	}
+	if len(reused.params) != 0 || len(reused.named) != 0 {
+		t.Error("A reused argument list should be empty")
+	}
+	if reused.params[:2][0] != nil || reused.named[:1][0].value != nil {
+		t.Error("A recycled argument list should not hold on to the arguments")
+	}
+	if vm.newCallParams(2) == params {
+		t.Error("An argument list in use should not be handed out twice")
+	}
+}
+
+func TestReuseFrame(t *testing.T) {
+	vm := New()
+	fn := &CompiledFunction{Name: "f", NumParams: 2, NumLocals: 4}
+	frame := vm.newCallFrame(fn)
+	first := vm.newCallParams(2)
+	frame.callParams = first
+	frame.setParam(0, types.NewInt(1))
+	frame.setParam(1, types.NewInt(2))
+	frame.setLocal(2, types.NewString("local"))
+	frame.ip = 7
+
+	// The second argument is the first parameter's value, which the
+	// parameters bound first must not lose
+	second := vm.newCallParams(1)
+	args := []*types.Value{types.NewInt(5), frame.getLocal(0)}
+	vm.reuseFrame(frame, second, args, nil)
+
+	if frame.ip != 0 {
+		t.Errorf("A reused frame should restart at 0, not %d", frame.ip)
+	}
+	if frame.getLocal(0).ToInt() != 5 || frame.getLocal(1).ToInt() != 1 {
+		t.Errorf("Parameters should be bound to 5 and 1, got %v and %v", frame.getLocal(0), frame.getLocal(1))
+	}
+	if frame.locals[2] != nil {
+		t.Error("Locals other than the parameters should be released")
+	}
+	if frame.callParams != second || len(frame.args) != 2 {
+		t.Error("The frame should hold the arguments of the new call")
+	}
+	if vm.newCallParams(0) != first {
+		t.Error("The arguments of the previous call should be recycled")
+	}
+}
+
+func TestFrameTailCalls(t *testing.T) {
+	frame := NewFrame(&CompiledFunction{Name: "f"})
+	for _, line := range []uint32{4, 4, 4, 6, 4} {
+		frame.addTailCall(line)
+	}
+	expected := []tailCall{{4, 3}, {6, 1}, {4, 1}}
+	if len(frame.tailCalls) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, frame.tailCalls)
+	}
+	for i, call := range expected {
+		if frame.tailCalls[i] != call {
+			t.Errorf("Expected %v, got %v", expected, frame.tailCalls)
+		}
+	}
+}
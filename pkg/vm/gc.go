@@ -0,0 +1,306 @@
+package vm
+
+import (
+	"slices"
+
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Cycle Collector
+// ============================================================================
+
+// The collector finds the objects a script can no longer reach, like PHP's
+// cycle collector. Objects of user classes count their holders (see
+// types/gc.go); one whose count drops without reaching zero is buffered as
+// a possible root of a garbage cycle. A collection marks everything
+// reachable from the VM state (variables of the call stack, globals,
+// static variables and properties, constants, registered callbacks) and
+// treats the buffered roots and the objects waiting for their destructor
+// that were not marked as garbage. Their destructors run, in creation
+// order; objects a destructor made reachable again survive. The VM then
+// drops its own references to the rest and Go reclaims them.
+//
+// Collections run on gc_collect_cycles() and, while zend.enable_gc is on,
+// when the root buffer reaches the threshold. Destructors of objects in
+// garbage cycles therefore run at one of these points, or at shutdown
+// with the other live objects, never at a fixed place in the script.
+
+const (
+	gcThresholdDefault = 10001      // Buffered roots that trigger a collection
+	gcThresholdStep    = 10000      // Threshold change after a collection
+	gcThresholdMax     = 1000000000 // Largest threshold
+	gcThresholdTrigger = 100        // Fewer freed objects raise the threshold
+	gcBufferSize       = 1 << 20    // Most buffered roots
+)
+
+// gcState is the state of the cycle collector
+type gcState struct {
+	enabled    bool
+	running    bool
+	destroying bool // Destructors of released objects are running
+	roots      *types.RootBuffer
+	threshold  int
+	runs       int
+	collected  int
+}
+
+// newGCState creates an enabled collector with an empty root buffer
+func newGCState() *gcState {
+	return &gcState{
+		enabled:   true,
+		roots:     types.NewRootBuffer(gcBufferSize),
+		threshold: gcThresholdDefault,
+	}
+}
+
+// trackObject registers a new object of a user class with the collector
+// and, if it has a destructor, with the shutdown sequence
+func (vm *VM) trackObject(obj *types.Object) {
+	obj.TrackRoots(vm.gc.roots)
+	vm.trackDestructor(obj)
+}
+
+// markRoots marks the objects reachable from the VM state. The collector's
+// own bookkeeping is not a root, and of the call stack only the frames in
+// use are; the frames and argument lists kept for reuse are not.
+func (vm *VM) markRoots(m *types.Marker) {
+	m.MarkFields(vm, "gc", "destructibles", "frames", "freeFrames", "freeParams")
+	for i := 0; i <= vm.frameIndex; i++ {
+		m.Mark(vm.frames[i])
+	}
+}
+
+// maybeCollectCycles runs a collection once the root buffer reaches the
+// threshold, if the collector is enabled
+func (vm *VM) maybeCollectCycles() error {
+	if !vm.gc.enabled || vm.gc.running || vm.gc.roots.Len() < vm.gc.threshold {
+		return nil
+	}
+	collected, err := vm.collectCycles()
+
+	// Collections that free little make the next one wait longer
+	if collected < gcThresholdTrigger {
+		vm.gc.threshold = min(vm.gc.threshold+gcThresholdStep, gcThresholdMax)
+	} else if vm.gc.threshold > gcThresholdDefault {
+		vm.gc.threshold = max(vm.gc.threshold-gcThresholdStep, gcThresholdDefault)
+	}
+	return err
+}
+
+// collectCycles runs a collection and returns the number of objects freed.
+// An exception thrown by a destructor ends the collection.
+func (vm *VM) collectCycles() (int, error) {
+	if vm.gc.running {
+		return 0, nil
+	}
+	vm.gc.running = true
+	defer func() { vm.gc.running = false }()
+
+	candidates := vm.gc.roots.Take()
+	candidates = append(candidates, vm.destructibles...)
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+
+	live := types.NewMarker()
+	vm.markRoots(live)
+	garbage := types.NewMarker()
+	for _, obj := range candidates {
+		if !live.Marked(obj) {
+			garbage.MarkObject(obj)
+		}
+	}
+	var unreachable []*types.Object
+	for _, obj := range garbage.Objects() {
+		if !live.Marked(obj) {
+			unreachable = append(unreachable, obj)
+		}
+	}
+	vm.gc.runs++
+	if len(unreachable) == 0 {
+		return 0, nil
+	}
+
+	// Destructors run in creation order, which the destructor list keeps
+	var err error
+	destructed := false
+	for _, obj := range vm.destructibles {
+		if garbage.Marked(obj) && !live.Marked(obj) && !obj.IsDestroyed {
+			destructed = true
+			if err = vm.destruct(obj); err != nil {
+				break
+			}
+		}
+	}
+	if destructed {
+		live = types.NewMarker()
+		vm.markRoots(live)
+	}
+
+	freed := 0
+	for _, obj := range unreachable {
+		if !live.Marked(obj) {
+			freed++
+		}
+	}
+	vm.destructibles = vm.retainLive(vm.destructibles, live, garbage)
+	vm.gc.collected += freed
+	return freed, err
+}
+
+// retainLive removes the freed garbage from a list of objects
+func (vm *VM) retainLive(objects []*types.Object, live, garbage *types.Marker) []*types.Object {
+	kept := objects[:0]
+	for _, obj := range objects {
+		if live.Marked(obj) || !garbage.Marked(obj) {
+			kept = append(kept, obj)
+		}
+	}
+	clear(objects[len(kept):])
+	return kept
+}
+
+// ============================================================================
+// Destructors
+// ============================================================================
+
+// An object whose last counted holder is dropped (a variable reassigned or
+// unset, a temporary freed, the locals of a returning function) is
+// released. After the instruction that released it, its destructor runs
+// unless the object is still reachable, as PHP destroys an object when its
+// last reference dies. A holder that is not counted, such as a reference
+// shared with a global or a static variable, keeps the object until a
+// later release, a collection or shutdown. An exception thrown by a
+// destructor is thrown by the releasing instruction.
+
+// releaseFrame drops the holders of the locals of a finished call.
+// References are left alone: what they point to may be shared.
+func (vm *VM) releaseFrame(frame *Frame) {
+	if frame == nil {
+		return
+	}
+	for _, local := range frame.locals {
+		types.DelRef(local)
+	}
+	vm.recycleFrame(frame)
+}
+
+// releaseReference drops the holder of the value of a reference a
+// variable unbinds, as the reference may not be shared
+func (vm *VM) releaseReference(ref *types.Value) {
+	if ref != nil && ref.IsReference() {
+		types.DelRef(ref.Deref())
+	}
+}
+
+// destroyReleased runs the destructors of the released objects that are no
+// longer held, in release order. Destructors releasing more objects destroy
+// them too.
+func (vm *VM) destroyReleased() error {
+	if vm.gc.destroying {
+		return nil
+	}
+	vm.gc.destroying = true
+	defer func() { vm.gc.destroying = false }()
+
+	for vm.gc.roots.HasReleased() {
+		var doomed []*types.Object
+		for _, obj := range vm.gc.roots.TakeReleased() {
+			if obj.RefCount() == 0 && !obj.IsDestroyed && destructorOf(obj.ClassEntry) != nil {
+				doomed = append(doomed, obj)
+			}
+		}
+		if len(doomed) == 0 {
+			continue
+		}
+
+		// A destructor may make the next object reachable again
+		var live *types.Marker
+		for _, obj := range doomed {
+			if live == nil {
+				live = types.NewMarker()
+				vm.markRoots(live)
+			}
+			if live.Marked(obj) || obj.IsDestroyed {
+				continue
+			}
+			vm.forgetDestructible(obj)
+			if err := vm.destruct(obj); err != nil {
+				return err
+			}
+			live = nil
+		}
+	}
+	return nil
+}
+
+// forgetDestructible removes a destroyed object from the objects the
+// shutdown sequence destroys
+func (vm *VM) forgetDestructible(obj *types.Object) {
+	if i := slices.Index(vm.destructibles, obj); i >= 0 {
+		vm.destructibles = slices.Delete(vm.destructibles, i, i+1)
+	}
+}
+
+// ============================================================================
+// GC Builtins
+// ============================================================================
+
+// registerGCBuiltins registers the garbage collector functions
+func (vm *VM) registerGCBuiltins() {
+	vm.RegisterBuiltin("gc_collect_cycles", builtinGCCollectCycles)
+	vm.RegisterBuiltin("gc_enable", builtinGCEnable)
+	vm.RegisterBuiltin("gc_disable", builtinGCDisable)
+	vm.RegisterBuiltin("gc_enabled", builtinGCEnabled)
+	vm.RegisterBuiltin("gc_status", builtinGCStatus)
+	vm.RegisterBuiltin("gc_mem_caches", builtinGCMemCaches)
+}
+
+// gc_collect_cycles(): int
+func builtinGCCollectCycles(vm *VM, args []*types.Value) (*types.Value, error) {
+	collected, err := vm.collectCycles()
+	if err != nil {
+		return nil, err
+	}
+	return types.NewInt(int64(collected)), nil
+}
+
+// gc_enable(): void
+func builtinGCEnable(vm *VM, args []*types.Value) (*types.Value, error) {
+	vm.config.Set("zend.enable_gc", "1", runtime.INI_USER)
+	return types.NewNull(), nil
+}
+
+// gc_disable(): void
+// Roots are still buffered, and gc_collect_cycles() still collects.
+func builtinGCDisable(vm *VM, args []*types.Value) (*types.Value, error) {
+	vm.config.Set("zend.enable_gc", "0", runtime.INI_USER)
+	return types.NewNull(), nil
+}
+
+// gc_enabled(): bool
+func builtinGCEnabled(vm *VM, args []*types.Value) (*types.Value, error) {
+	return types.NewBool(vm.gc.enabled), nil
+}
+
+// gc_status(): array
+func builtinGCStatus(vm *VM, args []*types.Value) (*types.Value, error) {
+	status := types.NewEmptyArray()
+	status.Set(types.NewString("runs"), types.NewInt(int64(vm.gc.runs)))
+	status.Set(types.NewString("collected"), types.NewInt(int64(vm.gc.collected)))
+	status.Set(types.NewString("threshold"), types.NewInt(int64(vm.gc.threshold)))
+	status.Set(types.NewString("roots"), types.NewInt(int64(vm.gc.roots.Len())))
+	status.Set(types.NewString("running"), types.NewBool(vm.gc.running))
+	status.Set(types.NewString("protected"), types.NewBool(false))
+	status.Set(types.NewString("full"), types.NewBool(vm.gc.roots.Full()))
+	status.Set(types.NewString("buffer_size"), types.NewInt(gcBufferSize))
+	return types.NewArray(status), nil
+}
+
+// gc_mem_caches(): int
+// Go's allocator keeps no caches PHP code can release.
+func builtinGCMemCaches(vm *VM, args []*types.Value) (*types.Value, error) {
+	return types.NewInt(0), nil
+}
@@ -0,0 +1,109 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// newTrackedObject creates an object the collector knows about
+func newTrackedObject(vm *VM, class *types.ClassEntry) *types.Object {
+	obj := types.NewObjectFromClass(class)
+	vm.trackObject(obj)
+	return obj
+}
+
+func TestGC_CollectsCycles(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+	classA := newDestructibleClass(vm, "A")
+	classB := newDestructibleClass(vm, "B")
+
+	// $a->b = $b; $b->a = $a; with neither in a variable
+	a, b := newTrackedObject(vm, classA), newTrackedObject(vm, classB)
+	a.AssignProperty("b", types.NewObject(b), nil)
+	b.AssignProperty("a", types.NewObject(a), nil)
+
+	// A cycle still held by a global survives
+	kept := newTrackedObject(vm, classA)
+	kept.AssignProperty("self", types.NewObject(kept), nil)
+	vm.SetGlobal("kept", types.NewObject(kept))
+
+	if collected := call("gc_collect_cycles"); collected.ToInt() != 2 {
+		t.Errorf("gc_collect_cycles() = %v, want 2", collected)
+	}
+	if vm.GetOutput() != "~A;~B;" {
+		t.Errorf("destructors output %q", vm.GetOutput())
+	}
+	if len(vm.destructibles) != 1 || vm.destructibles[0] != kept {
+		t.Errorf("destructibles = %v", vm.destructibles)
+	}
+
+	status := call("gc_status").ToArray()
+	if runs, _ := status.Get(types.NewString("runs")); runs.ToInt() != 1 {
+		t.Errorf("gc_status() runs = %v", runs)
+	}
+	if collected, _ := status.Get(types.NewString("collected")); collected.ToInt() != 2 {
+		t.Errorf("gc_status() collected = %v", collected)
+	}
+}
+
+func TestGC_DestructorResurrects(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+	class := types.NewClassEntry("Phoenix")
+	addNativeMethod(class, "__destruct", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		vm.SetGlobal("saved", types.NewObject(this))
+		return types.NewNull(), nil
+	})
+	vm.classes["Phoenix"] = class
+
+	obj := newTrackedObject(vm, class)
+	obj.AssignProperty("self", types.NewObject(obj), nil)
+	if collected := call("gc_collect_cycles"); collected.ToInt() != 0 {
+		t.Errorf("gc_collect_cycles() = %v, want 0", collected)
+	}
+	if !obj.IsDestroyed {
+		t.Error("the destructor did not run")
+	}
+	if saved, ok := vm.GetGlobal("saved"); !ok || saved.ToObject() != obj {
+		t.Error("the resurrected object was lost")
+	}
+}
+
+func TestGC_EnableDisable(t *testing.T) {
+	vm := New()
+	call := sessionCaller(t, vm)
+
+	call("gc_disable")
+	if call("gc_enabled").ToBool() || call("ini_get", types.NewString("zend.enable_gc")).ToString() != "0" {
+		t.Error("gc_disable() did not disable the collector")
+	}
+	call("ini_set", types.NewString("zend.enable_gc"), types.NewString("1"))
+	if !call("gc_enabled").ToBool() {
+		t.Error("zend.enable_gc did not enable the collector")
+	}
+}
+
+func TestGC_ThresholdTriggersCollection(t *testing.T) {
+	vm := New()
+	class := newDestructibleClass(vm, "Node")
+	vm.gc.threshold = 2
+
+	// Possible roots: objects whose count dropped but not to zero
+	for i := 0; i < 2; i++ {
+		obj := newTrackedObject(vm, class)
+		obj.AssignProperty("self", types.NewObject(obj), nil)
+		obj.AddRef()
+		obj.DelRef()
+	}
+	if err := vm.maybeCollectCycles(); err != nil {
+		t.Fatal(err)
+	}
+	if vm.gc.runs != 1 || vm.GetOutput() != "~Node;~Node;" {
+		t.Errorf("runs = %d, output %q", vm.gc.runs, vm.GetOutput())
+	}
+	if vm.gc.threshold != 2+gcThresholdStep {
+		t.Errorf("threshold = %d", vm.gc.threshold)
+	}
+}
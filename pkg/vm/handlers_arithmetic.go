@@ -13,35 +13,44 @@ import (
 
 // opAdd handles addition (result = op1 + op2)
 func (vm *VM) opAdd(frame *Frame, instr Instruction) error {
-	left, err := vm.getOperandValue(frame, instr.Op1)
-	if err != nil {
-		return err
-	}
+	return vm.opBinary(frame, instr)
+}
 
-	right, err := vm.getOperandValue(frame, instr.Op2)
-	if err != nil {
-		return err
-	}
+// opSub handles subtraction (result = op1 - op2)
+func (vm *VM) opSub(frame *Frame, instr Instruction) error {
+	return vm.opBinary(frame, instr)
+}
 
-	// PHP addition rules:
-	// - If both are ints, result is int
-	// - If either is float, result is float
-	// - Otherwise convert to numeric
+// opMul handles multiplication (result = op1 * op2)
+func (vm *VM) opMul(frame *Frame, instr Instruction) error {
+	return vm.opBinary(frame, instr)
+}
 
-	var result *types.Value
+// opDiv handles division (result = op1 / op2)
+func (vm *VM) opDiv(frame *Frame, instr Instruction) error {
+	return vm.opBinary(frame, instr)
+}
 
-	if left.IsInt() && right.IsInt() {
-		result = types.NewInt(left.ToInt() + right.ToInt())
-	} else {
-		// At least one is float, or needs conversion
-		result = types.NewFloat(left.ToFloat() + right.ToFloat())
-	}
+// opMod handles modulo (result = op1 % op2)
+func (vm *VM) opMod(frame *Frame, instr Instruction) error {
+	return vm.opBinary(frame, instr)
+}
 
-	return vm.setOperandValue(frame, instr.Result, result)
+// opPow handles exponentiation (result = op1 ** op2)
+func (vm *VM) opPow(frame *Frame, instr Instruction) error {
+	return vm.opBinary(frame, instr)
 }
 
-// opSub handles subtraction (result = op1 - op2)
-func (vm *VM) opSub(frame *Frame, instr Instruction) error {
+// Set in the ExtendedValue of ADD, SUB and MUL by the data-flow optimizer
+// when it inferred the types of both operands: ints, or numbers with at
+// least one float
+const (
+	ArithLong   uint32 = 1
+	ArithDouble uint32 = 2
+)
+
+// opBinary evaluates an arithmetic, bitwise or concatenation instruction
+func (vm *VM) opBinary(frame *Frame, instr Instruction) error {
 	left, err := vm.getOperandValue(frame, instr.Op1)
 	if err != nil {
 		return err
@@ -52,110 +61,229 @@ func (vm *VM) opSub(frame *Frame, instr Instruction) error {
 		return err
 	}
 
-	var result *types.Value
+	if result := specializedArithmetic(instr, left, right); result != nil {
+		return vm.setOperandValue(frame, instr.Result, result)
+	}
 
-	if left.IsInt() && right.IsInt() {
-		result = types.NewInt(left.ToInt() - right.ToInt())
-	} else {
-		result = types.NewFloat(left.ToFloat() - right.ToFloat())
+	result, err := vm.binaryOp(instr.Opcode, left, right)
+	if err != nil {
+		return err
 	}
 
 	return vm.setOperandValue(frame, instr.Result, result)
 }
 
-// opMul handles multiplication (result = op1 * op2)
-func (vm *VM) opMul(frame *Frame, instr Instruction) error {
-	left, err := vm.getOperandValue(frame, instr.Op1)
-	if err != nil {
-		return err
+// specializedArithmetic computes ADD, SUB or MUL on the operand types the
+// optimizer inferred without the conversions of binaryOp; nil if the
+// instruction is not specialized or the operands are not of those types
+func specializedArithmetic(instr Instruction, left, right *types.Value) *types.Value {
+	switch instr.ExtendedValue {
+	case ArithLong:
+		if left.IsInt() && right.IsInt() {
+			return arithmetic(instr.Opcode, left, right)
+		}
+	case ArithDouble:
+		if (left.IsFloat() || right.IsFloat()) && (left.IsInt() || left.IsFloat()) && (right.IsInt() || right.IsFloat()) {
+			a, b := left.ToFloat(), right.ToFloat()
+			switch instr.Opcode {
+			case OpAdd:
+				return types.NewFloat(a + b)
+			case OpSub:
+				return types.NewFloat(a - b)
+			case OpMul:
+				return types.NewFloat(a * b)
+			}
+		}
 	}
+	return nil
+}
 
-	right, err := vm.getOperandValue(frame, instr.Op2)
-	if err != nil {
-		return err
-	}
+// ============================================================================
+// Binary Operators
+// ============================================================================
 
-	var result *types.Value
+// binaryOperators maps the opcodes of the binary operators, which compound
+// assignments name in their ExtendedValue, to their PHP spelling
+var binaryOperators = map[Opcode]string{
+	OpAdd:    "+",
+	OpSub:    "-",
+	OpMul:    "*",
+	OpDiv:    "/",
+	OpMod:    "%",
+	OpPow:    "**",
+	OpConcat: ".",
+	OpBWAnd:  "&",
+	OpBWOr:   "|",
+	OpBWXor:  "^",
+	OpSL:     "<<",
+	OpSR:     ">>",
+}
 
-	if left.IsInt() && right.IsInt() {
-		result = types.NewInt(left.ToInt() * right.ToInt())
-	} else {
-		result = types.NewFloat(left.ToFloat() * right.ToFloat())
-	}
+// binaryOperatorSpellings holds binaryOperators by opcode, sparing the
+// operations a map lookup; "" for the other opcodes
+var binaryOperatorSpellings [OpcodeLast + 1]string
 
-	return vm.setOperandValue(frame, instr.Result, result)
+func init() {
+	for op, operator := range binaryOperators {
+		binaryOperatorSpellings[op] = operator
+	}
 }
 
-// opDiv handles division (result = op1 / op2)
-func (vm *VM) opDiv(frame *Frame, instr Instruction) error {
-	left, err := vm.getOperandValue(frame, instr.Op1)
-	if err != nil {
-		return err
+// binaryOp applies a binary operator following PHP's conversion rules:
+// numeric strings act as numbers, leading-numeric strings too but with a
+// warning, and non-numeric strings, arrays (except for array union) and
+// objects throw a TypeError. Integer results that overflow become floats.
+func (vm *VM) binaryOp(op Opcode, left, right *types.Value) (*types.Value, error) {
+	operator := binaryOperatorSpellings[op]
+	if operator == "" {
+		return nil, fmt.Errorf("%s is not a binary operator", op)
+	}
+	left, right = left.Deref(), right.Deref()
+
+	switch op {
+	case OpConcat:
+		l, err := vm.stringValue(left)
+		if err != nil {
+			return nil, err
+		}
+		r, err := vm.stringValue(right)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewString(l + r), nil
+	case OpAdd:
+		if left.IsArray() && right.IsArray() {
+			return arrayUnion(left.ToArray(), right.ToArray()), nil
+		}
+	case OpBWAnd, OpBWOr, OpBWXor:
+		if left.IsString() && right.IsString() {
+			return types.NewString(bitwiseStrings(op, left.ToString(), right.ToString())), nil
+		}
 	}
 
-	right, err := vm.getOperandValue(frame, instr.Op2)
+	l, err := vm.toNumber(operator, left, right, left)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	// Division by zero check
-	if right.ToFloat() == 0.0 {
-		return fmt.Errorf("division by zero")
+	r, err := vm.toNumber(operator, left, right, right)
+	if err != nil {
+		return nil, err
 	}
 
-	// Division always returns float in PHP
-	result := types.NewFloat(left.ToFloat() / right.ToFloat())
-
-	return vm.setOperandValue(frame, instr.Result, result)
+	switch op {
+	case OpAdd, OpSub, OpMul:
+		return arithmetic(op, l, r), nil
+	case OpDiv:
+		if r.ToFloat() == 0 {
+			return nil, vm.ThrowError("DivisionByZeroError", "Division by zero")
+		}
+		if l.IsInt() && r.IsInt() {
+			a, b := l.ToInt(), r.ToInt()
+			if a%b == 0 && !(a == math.MinInt64 && b == -1) {
+				return types.NewInt(a / b), nil
+			}
+		}
+		return types.NewFloat(l.ToFloat() / r.ToFloat()), nil
+	case OpMod:
+		a, b := l.ToInt(), r.ToInt()
+		if b == 0 {
+			return nil, vm.ThrowError("DivisionByZeroError", "Modulo by zero")
+		}
+		if b == -1 {
+			// Avoids the overflow of MinInt64 % -1
+			return types.NewInt(0), nil
+		}
+		return types.NewInt(a % b), nil
+	case OpPow:
+		return types.Pow(l, r), nil
+	case OpBWAnd:
+		return types.NewInt(l.ToInt() & r.ToInt()), nil
+	case OpBWOr:
+		return types.NewInt(l.ToInt() | r.ToInt()), nil
+	case OpBWXor:
+		return types.NewInt(l.ToInt() ^ r.ToInt()), nil
+	default:
+		a, shift := l.ToInt(), r.ToInt()
+		if shift < 0 {
+			return nil, vm.ThrowError("ArithmeticError", "Bit shift by negative number")
+		}
+		if shift >= 64 {
+			if op == OpSR && a < 0 {
+				return types.NewInt(-1), nil
+			}
+			return types.NewInt(0), nil
+		}
+		if op == OpSL {
+			return types.NewInt(a << uint(shift)), nil
+		}
+		return types.NewInt(a >> uint(shift)), nil
+	}
 }
 
-// opMod handles modulo (result = op1 % op2)
-func (vm *VM) opMod(frame *Frame, instr Instruction) error {
-	left, err := vm.getOperandValue(frame, instr.Op1)
-	if err != nil {
-		return err
+// toNumber converts an operand of a binary operator to an int or float,
+// see types.ToNumber
+func (vm *VM) toNumber(operator string, left, right, operand *types.Value) (*types.Value, error) {
+	number, kind, ok := types.ToNumber(operand)
+	if !ok {
+		return nil, vm.ThrowError("TypeError", "Unsupported operand types: %s %s %s", left.TypeName(), operator, right.TypeName())
 	}
-
-	right, err := vm.getOperandValue(frame, instr.Op2)
-	if err != nil {
-		return err
+	if kind == types.LeadingNumeric {
+		vm.warning("A non-numeric value encountered")
 	}
+	return number, nil
+}
 
-	// Convert to integers for modulo
-	leftInt := left.ToInt()
-	rightInt := right.ToInt()
-
-	// Modulo by zero check
-	if rightInt == 0 {
-		return fmt.Errorf("modulo by zero")
+// arithmetic adds, subtracts or multiplies two numbers, promoting integer
+// results that overflow to float
+func arithmetic(op Opcode, l, r *types.Value) *types.Value {
+	switch op {
+	case OpAdd:
+		return types.Add(l, r)
+	case OpSub:
+		return types.Sub(l, r)
+	default:
+		return types.Mul(l, r)
 	}
+}
 
-	// Modulo returns int
-	result := types.NewInt(leftInt % rightInt)
-
-	return vm.setOperandValue(frame, instr.Result, result)
+// arrayUnion returns left + right: left's elements plus the elements of
+// right whose keys left does not have
+func arrayUnion(left, right *types.Array) *types.Value {
+	arr := left.Copy()
+	right.Each(func(key, value *types.Value) bool {
+		if !arr.HasKey(key) {
+			arr.Set(key, value)
+		}
+		return true
+	})
+	return types.NewArray(arr)
 }
 
-// opPow handles exponentiation (result = op1 ** op2)
-func (vm *VM) opPow(frame *Frame, instr Instruction) error {
-	left, err := vm.getOperandValue(frame, instr.Op1)
-	if err != nil {
-		return err
+// bitwiseStrings applies &, | or ^ to the bytes of two strings. The result
+// has the length of the shorter string, except for | which keeps the
+// remaining bytes of the longer one.
+func bitwiseStrings(op Opcode, a, b string) string {
+	if len(a) < len(b) {
+		a, b = b, a
 	}
-
-	right, err := vm.getOperandValue(frame, instr.Op2)
-	if err != nil {
-		return err
+	n := len(b)
+	if op == OpBWOr {
+		n = len(a)
 	}
-
-	// Convert to floats for power
-	base := left.ToFloat()
-	exp := right.ToFloat()
-
-	// Calculate power
-	result := types.NewFloat(math.Pow(base, exp))
-
-	return vm.setOperandValue(frame, instr.Result, result)
+	out := make([]byte, n)
+	for i := range out {
+		switch {
+		case i >= len(b):
+			out[i] = a[i]
+		case op == OpBWAnd:
+			out[i] = a[i] & b[i]
+		case op == OpBWOr:
+			out[i] = a[i] | b[i]
+		default:
+			out[i] = a[i] ^ b[i]
+		}
+	}
+	return string(out)
 }
 
 // opNegate handles unary negation (result = -op1)
@@ -0,0 +1,152 @@
+package vm
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Binary Operator Tests
+// ============================================================================
+
+func TestBinaryOp(t *testing.T) {
+	arr := func(values ...*types.Value) *types.Value {
+		return types.NewArray(types.NewArrayFromSlice(values))
+	}
+
+	tests := []struct {
+		name        string
+		op          Opcode
+		left, right *types.Value
+		want        *types.Value
+	}{
+		{"int add", OpAdd, types.NewInt(2), types.NewInt(3), types.NewInt(5)},
+		{"numeric string add", OpAdd, types.NewString("5"), types.NewInt(3), types.NewInt(8)},
+		{"float string add", OpAdd, types.NewString(" 1.5"), types.NewInt(1), types.NewFloat(2.5)},
+		{"null and bool", OpAdd, types.NewNull(), types.NewBool(true), types.NewInt(1)},
+		{"add overflow", OpAdd, types.NewInt(math.MaxInt64), types.NewInt(1), types.NewFloat(math.MaxInt64 + 1.0)},
+		{"sub overflow", OpSub, types.NewInt(math.MinInt64), types.NewInt(1), types.NewFloat(math.MinInt64 - 1.0)},
+		{"mul overflow", OpMul, types.NewInt(math.MaxInt64), types.NewInt(2), types.NewFloat(math.MaxInt64 * 2.0)},
+		{"mul min int", OpMul, types.NewInt(math.MinInt64), types.NewInt(1), types.NewInt(math.MinInt64)},
+		{"exact division", OpDiv, types.NewInt(20), types.NewInt(4), types.NewInt(5)},
+		{"inexact division", OpDiv, types.NewInt(7), types.NewInt(2), types.NewFloat(3.5)},
+		{"modulo", OpMod, types.NewInt(-7), types.NewInt(3), types.NewInt(-1)},
+		{"modulo truncates floats", OpMod, types.NewFloat(7.9), types.NewString("2"), types.NewInt(1)},
+		{"int power", OpPow, types.NewInt(2), types.NewInt(10), types.NewInt(1024)},
+		{"power overflow", OpPow, types.NewInt(2), types.NewInt(64), types.NewFloat(math.Pow(2, 64))},
+		{"negative exponent", OpPow, types.NewInt(2), types.NewInt(-1), types.NewFloat(0.5)},
+		{"concat", OpConcat, types.NewInt(1), types.NewString("a"), types.NewString("1a")},
+		{"string and", OpBWAnd, types.NewString("ab"), types.NewString("a"), types.NewString("a")},
+		{"string or", OpBWOr, types.NewString("a"), types.NewString("  "), types.NewString("a ")},
+		{"int xor", OpBWXor, types.NewString("6"), types.NewInt(3), types.NewInt(5)},
+		{"shift left", OpSL, types.NewInt(1), types.NewInt(4), types.NewInt(16)},
+		{"shift past width", OpSL, types.NewInt(1), types.NewInt(64), types.NewInt(0)},
+		{"arithmetic shift right", OpSR, types.NewInt(-8), types.NewInt(100), types.NewInt(-1)},
+	}
+
+	vm := New()
+	for _, tt := range tests {
+		got, err := vm.binaryOp(tt.op, tt.left, tt.right)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !got.Identical(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	union, err := vm.binaryOp(OpAdd, arr(types.NewInt(1)), arr(types.NewInt(9), types.NewInt(2)))
+	if err != nil {
+		t.Fatalf("array union: unexpected error: %v", err)
+	}
+	if union.ToArray().Len() != 2 {
+		t.Errorf("array union: expected 2 elements, got %d", union.ToArray().Len())
+	}
+	if first, _ := union.ToArray().Get(types.NewInt(0)); first.ToInt() != 1 {
+		t.Errorf("array union: expected the left element to win, got %v", first)
+	}
+}
+
+func TestBinaryOp_LeadingNumericWarns(t *testing.T) {
+	vm := New()
+	got, err := vm.binaryOp(OpAdd, types.NewString("10 apples"), types.NewInt(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.Identical(types.NewInt(11)) {
+		t.Errorf("Expected 11, got %v", got)
+	}
+	if !strings.Contains(vm.GetOutput(), "Warning: A non-numeric value encountered") {
+		t.Errorf("Expected a non-numeric warning, got %q", vm.GetOutput())
+	}
+}
+
+func TestBinaryOp_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		op          Opcode
+		left, right *types.Value
+		class       string
+		message     string
+	}{
+		{"non-numeric string", OpAdd, types.NewString("abc"), types.NewInt(1), "TypeError", "Unsupported operand types: string + int"},
+		{"array operand", OpMul, types.NewArray(types.NewEmptyArray()), types.NewInt(2), "TypeError", "Unsupported operand types: array * int"},
+		{"division by zero", OpDiv, types.NewInt(1), types.NewInt(0), "DivisionByZeroError", "Division by zero"},
+		{"modulo by zero", OpMod, types.NewInt(1), types.NewString("0"), "DivisionByZeroError", "Modulo by zero"},
+		{"negative shift", OpSL, types.NewInt(1), types.NewInt(-1), "ArithmeticError", "Bit shift by negative number"},
+	}
+
+	for _, tt := range tests {
+		vm := New()
+		_, err := vm.binaryOp(tt.op, tt.left, tt.right)
+		thrown, ok := err.(*ThrowableError)
+		if !ok {
+			t.Errorf("%s: expected a thrown %s, got %v", tt.name, tt.class, err)
+			continue
+		}
+		if thrown.Object.ClassName != tt.class {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.class, thrown.Object.ClassName)
+		}
+		if msg := throwableProperty(thrown.Object, "message"); msg.ToString() != tt.message {
+			t.Errorf("%s: expected message %q, got %q", tt.name, tt.message, msg.ToString())
+		}
+	}
+}
+
+func TestSpecializedArithmetic(t *testing.T) {
+	instr := func(op Opcode, hint uint32) Instruction {
+		return Instruction{Opcode: op, ExtendedValue: hint}
+	}
+
+	tests := []struct {
+		name        string
+		instr       Instruction
+		left, right *types.Value
+		want        *types.Value // nil when binaryOp should handle it
+	}{
+		{"long add", instr(OpAdd, ArithLong), types.NewInt(2), types.NewInt(3), types.NewInt(5)},
+		{"long overflow", instr(OpMul, ArithLong), types.NewInt(math.MaxInt64), types.NewInt(2), types.NewFloat(math.MaxInt64 * 2.0)},
+		{"double sub", instr(OpSub, ArithDouble), types.NewFloat(2.5), types.NewInt(1), types.NewFloat(1.5)},
+		{"double mul", instr(OpMul, ArithDouble), types.NewInt(3), types.NewFloat(0.5), types.NewFloat(1.5)},
+		{"not specialized", instr(OpAdd, 0), types.NewInt(2), types.NewInt(3), nil},
+		{"long hint on a string", instr(OpAdd, ArithLong), types.NewString("2"), types.NewInt(3), nil},
+		{"double hint on ints", instr(OpAdd, ArithDouble), types.NewInt(2), types.NewInt(3), nil},
+	}
+
+	for _, tt := range tests {
+		got := specializedArithmetic(tt.instr, tt.left, tt.right)
+		if tt.want == nil {
+			if got != nil {
+				t.Errorf("%s: expected the generic path, got %v", tt.name, got)
+			}
+			continue
+		}
+		if got == nil || !got.Identical(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
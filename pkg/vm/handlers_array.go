@@ -2,6 +2,7 @@ package vm
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/krizos/php-go/pkg/types"
 )
@@ -48,10 +49,10 @@ func (vm *VM) opAddArrayElement(frame *Frame, instr Instruction) error {
 		if err != nil {
 			return err
 		}
-		arr.Set(key, value)
+		arr.Set(key, assignValue(value))
 	} else {
 		// Append: result[] = op1
-		arr.Append(value)
+		arr.Append(assignValue(value))
 	}
 
 	return nil
@@ -87,19 +88,27 @@ func (vm *VM) opFetchDimR(frame *Frame, instr Instruction) error {
 			// PHP returns NULL for undefined array keys (with notice)
 			result = types.NewNull()
 		} else {
-			result = val
+			result = val.Deref()
 		}
 
 	case types.TypeString:
 		// String offset access: $str[$index]
 		str := container.ToString()
-		index := int(key.ToInt())
+		offset, err := vm.stringOffsetIndex(key)
+		if err != nil {
+			return err
+		}
+		// Negative offsets count from the end
+		index := offset
+		if index < 0 {
+			index += int64(len(str))
+		}
 
-		if index < 0 || index >= len(str) {
-			// Out of bounds
+		if index < 0 || index >= int64(len(str)) {
+			vm.warning("Uninitialized string offset %d", offset)
 			result = types.NewString("")
 		} else {
-			result = types.NewString(string(str[index]))
+			result = types.NewString(str[index : index+1])
 		}
 
 	default:
@@ -110,55 +119,55 @@ func (vm *VM) opFetchDimR(frame *Frame, instr Instruction) error {
 	return vm.setOperandValue(frame, instr.Result, result)
 }
 
-// opFetchDimW handles fetching array element for write: $arr[$key] = ...
+// opFetchDimW fetches an array element to write into: $arr[$key][...] = ...
+// A missing or null element (and a null container) becomes an empty array
+// in place, so the elements the next instruction writes land in the
+// container. Without a key, a new element is appended.
 // OpFetchDimW - Fetch array element for write
 func (vm *VM) opFetchDimW(frame *Frame, instr Instruction) error {
-	// Get the array
 	container, err := vm.getOperandValue(frame, instr.Op1)
 	if err != nil {
 		return err
 	}
 
-	// For write operations, we need to ensure we have an array
-	if container.Type() != types.TypeArray {
-		// PHP auto-vivifies to an array
-		newArr := types.NewEmptyArray()
-		container = types.NewArray(newArr)
-		// Update the original variable
-		vm.setOperandValue(frame, instr.Op1, container)
+	switch container.Type() {
+	case types.TypeArray:
+	case types.TypeUndef, types.TypeNull:
+		container = types.NewArray(types.NewEmptyArray())
+		if err := vm.setOperandValue(frame, instr.Op1, container); err != nil {
+			return err
+		}
+	case types.TypeString:
+		return vm.ThrowError("Error", "Cannot use string offset as an array")
+	default:
+		return vm.ThrowError("Error", "Cannot use a scalar value as an array")
 	}
 
 	arr := container.ToArray()
 
-	// Get the key (might be unspecified for append operation)
-	var key *types.Value
-	if instr.Op2.Type != OpUnused {
-		key, err = vm.getOperandValue(frame, instr.Op2)
-		if err != nil {
-			return err
-		}
-	} else {
-		// No key specified - this is for append operation $arr[] = ...
-		// We'll use the next auto-increment index
-		key = nil
+	// The element is modified in place, so it must not be shared with copies
+	arr.Separate()
+
+	if instr.Op2.Type == OpUnused {
+		element := types.NewArray(types.NewEmptyArray())
+		arr.Append(element)
+		return vm.setOperandValue(frame, instr.Result, element)
+	}
+	key, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
 	}
 
-	// For write fetch, we return a reference to the array element
-	// In a simplified VM, we'll create/get the element
-	if key != nil {
-		// Check if element exists
-		val, exists := arr.Get(key)
-		if !exists {
-			// Create new element
-			val = types.NewNull()
-			arr.Set(key, val)
-		}
-		return vm.setOperandValue(frame, instr.Result, val)
-	} else {
-		// Append operation - create a placeholder
-		// The actual append will happen in OpAssignDim
-		return vm.setOperandValue(frame, instr.Result, types.NewNull())
+	element, exists := arr.Get(key)
+	if exists && element.Deref().Type() != types.TypeNull {
+		return vm.setOperandValue(frame, instr.Result, element.Deref())
+	}
+	array := types.NewArray(types.NewEmptyArray())
+	// Elements bound to a reference are written through
+	if !exists || !element.Assign(array) {
+		arr.Set(key, array)
 	}
+	return vm.setOperandValue(frame, instr.Result, array)
 }
 
 // opFetchDimRW handles fetching array element for read-write: $arr[$key] += 1
@@ -184,15 +193,21 @@ func (vm *VM) opFetchDimIs(frame *Frame, instr Instruction) error {
 
 	var result *types.Value
 
-	if container.Type() == types.TypeArray {
+	switch container.Type() {
+	case types.TypeArray:
 		arr := container.ToArray()
 		val, exists := arr.Get(key)
 		if !exists {
 			result = types.NewNull()
 		} else {
-			result = val
+			result = val.Deref()
 		}
-	} else {
+	case types.TypeString:
+		var exists bool
+		if result, exists = stringOffset(container.ToString(), key); !exists {
+			result = types.NewNull()
+		}
+	default:
 		result = types.NewNull()
 	}
 
@@ -206,11 +221,120 @@ func (vm *VM) opFetchDimFuncArg(frame *Frame, instr Instruction) error {
 	return vm.opFetchDimR(frame, instr)
 }
 
-// opFetchDimUnset handles fetching array element for unset
+// opFetchDimUnset fetches an array element whose own element is unset:
+// the $a['x'] of unset($a['x']['y']). The element is returned in place,
+// separated from copies of the array; missing elements are not created.
 // OpFetchDimUnset - Fetch array element for unset
 func (vm *VM) opFetchDimUnset(frame *Frame, instr Instruction) error {
-	// For unset, we just need to identify the element
-	return vm.opFetchDimR(frame, instr)
+	container, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	container = container.Deref()
+	if container.Type() != types.TypeArray {
+		return vm.setOperandValue(frame, instr.Result, types.NewNull())
+	}
+
+	key, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+
+	arr := container.ToArray()
+	arr.Separate()
+	element, exists := arr.Get(key)
+	if !exists {
+		element = types.NewNull()
+	}
+	return vm.setOperandValue(frame, instr.Result, element)
+}
+
+// ============================================================================
+// List Operations
+// ============================================================================
+
+// opFetchListR fetches an element for a list() assignment:
+// result = op1[op2]. A missing key warns and yields null; anything but an
+// array destructures to nulls.
+// OpFetchListR - Fetch for list() assignment
+func (vm *VM) opFetchListR(frame *Frame, instr Instruction) error {
+	container, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	key, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+
+	result := types.NewNull()
+	if container := container.Deref(); container.IsArray() {
+		if val, exists := container.ToArray().Get(key); exists {
+			result = val.Deref()
+		} else {
+			vm.warning("Undefined array key %s", arrayKeyLabel(key))
+		}
+	}
+	return vm.setOperandValue(frame, instr.Result, result)
+}
+
+// opFetchListW fetches an element by reference for a list() assignment
+// with &: result = &op1[op2]. The element is created if missing and op1
+// (a variable, or a reference fetched by an enclosing list) becomes an
+// array if it is null.
+// OpFetchListW - Fetch for list() assignment (write)
+func (vm *VM) opFetchListW(frame *Frame, instr Instruction) error {
+	container, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	key, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+
+	switch container.Deref().Type() {
+	case types.TypeArray:
+		container = container.Deref()
+	case types.TypeUndef, types.TypeNull:
+		array := types.NewArray(types.NewEmptyArray())
+		if !container.Assign(array) {
+			if err := vm.setOperandValue(frame, instr.Op1, array); err != nil {
+				return err
+			}
+		}
+		container = array
+	default:
+		return vm.ThrowError("Error", "Cannot use a scalar value as an array")
+	}
+
+	return vm.setOperandValue(frame, instr.Result, elementReference(container.ToArray(), key))
+}
+
+// elementReference returns the element of an array under key as a
+// reference, turning the element into one (and creating it if missing)
+func elementReference(arr *types.Array, key *types.Value) *types.Value {
+	// The element is bound in place, so it must not be shared with copies
+	arr.Separate()
+
+	element, exists := arr.Get(key)
+	if !exists || !element.IsReference() {
+		if !exists {
+			element = types.NewNull()
+		}
+		element = types.NewReference(element)
+		arr.Set(key, element)
+	}
+	return element
+}
+
+// arrayKeyLabel formats an array key as PHP shows it in warnings: integers
+// bare, strings quoted
+func arrayKeyLabel(key *types.Value) string {
+	if key := key.Deref(); key.IsString() {
+		return `"` + key.ToString() + `"`
+	}
+	return key.ToString()
 }
 
 // ============================================================================
@@ -226,6 +350,18 @@ func (vm *VM) opAssignDim(frame *Frame, instr Instruction) error {
 		return err
 	}
 
+	switch container.Type() {
+	case types.TypeArray:
+	case types.TypeString:
+		return vm.assignStringOffset(frame, instr, container.ToString())
+	case types.TypeInt, types.TypeFloat:
+		return vm.ThrowError("Error", "Cannot use a scalar value as an array")
+	case types.TypeBool:
+		if container.ToBool() {
+			return vm.ThrowError("Error", "Cannot use a scalar value as an array")
+		}
+	}
+
 	// Auto-vivify to array if needed
 	if container.Type() != types.TypeArray {
 		newArr := types.NewEmptyArray()
@@ -248,10 +384,14 @@ func (vm *VM) opAssignDim(frame *Frame, instr Instruction) error {
 		if err != nil {
 			return err
 		}
-		arr.Set(key, value)
+		// Elements bound to a reference ($GLOBALS) are written through
+		if current, ok := arr.Get(key); ok && current.Assign(assignValue(value)) {
+			return nil
+		}
+		arr.Set(key, assignValue(value))
 	} else {
 		// Append: $arr[] = $value
-		arr.Append(value)
+		arr.Append(assignValue(value))
 	}
 
 	return nil
@@ -266,8 +406,18 @@ func (vm *VM) opAssignDimOp(frame *Frame, instr Instruction) error {
 		return err
 	}
 
-	if container.Type() != types.TypeArray {
-		return fmt.Errorf("ASSIGN_DIM_OP: container is not an array")
+	// Auto-vivify null to array, like ASSIGN_DIM
+	switch container.Type() {
+	case types.TypeArray:
+	case types.TypeUndef, types.TypeNull:
+		container = types.NewArray(types.NewEmptyArray())
+		if err := vm.setOperandValue(frame, instr.Op1, container); err != nil {
+			return err
+		}
+	case types.TypeString:
+		return vm.ThrowError("Error", "Cannot use assign-op operators with string offsets")
+	default:
+		return vm.ThrowError("Error", "Cannot use a scalar value as an array")
 	}
 
 	arr := container.ToArray()
@@ -290,15 +440,96 @@ func (vm *VM) opAssignDimOp(frame *Frame, instr Instruction) error {
 		return err
 	}
 
-	// Perform the operation (this would come from extended opcode info)
-	// For simplicity, assume += operation
-	// TODO: Get actual operation type from instruction
-	newVal := types.NewInt(currentVal.ToInt() + operand.ToInt())
+	// ExtendedValue holds the opcode of the binary operator
+	newVal, err := vm.binaryOp(Opcode(instr.ExtendedValue), currentVal, operand)
+	if err != nil {
+		return err
+	}
 
-	arr.Set(key, newVal)
+	// Elements bound to a reference ($GLOBALS) are written through
+	if !currentVal.Assign(newVal) {
+		arr.Set(key, newVal)
+	}
 	return nil
 }
 
+// assignStringOffset handles ASSIGN_DIM on a string: op1[op2] = result
+// replaces the byte at the offset with the first byte of the value,
+// padding the string with spaces when the offset is past its end.
+// Negative offsets count from the end; one before the start only warns.
+// The assigned character replaces the value in result.
+func (vm *VM) assignStringOffset(frame *Frame, instr Instruction, str string) error {
+	if instr.Op2.Type == OpUnused {
+		return vm.ThrowError("Error", "[] operator not supported for strings")
+	}
+	key, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+	offset, err := vm.stringOffsetIndex(key)
+	if err != nil {
+		return err
+	}
+	index := offset
+	if index < 0 {
+		index += int64(len(str))
+	}
+	if index < 0 {
+		vm.warning("Illegal string offset %d", offset)
+		return vm.setOperandValue(frame, instr.Result, types.NewNull())
+	}
+
+	value, err := vm.getOperandValue(frame, instr.Result)
+	if err != nil {
+		return err
+	}
+	char, err := vm.stringValue(value)
+	if err != nil {
+		return err
+	}
+	switch {
+	case char == "":
+		return vm.ThrowError("Error", "Cannot assign an empty string to a string offset")
+	case len(char) > 1:
+		vm.warning("Only the first byte will be assigned to the string offset")
+	}
+
+	buf := []byte(str)
+	for int64(len(buf)) <= index {
+		buf = append(buf, ' ')
+	}
+	buf[index] = char[0]
+	if err := vm.setOperandValue(frame, instr.Op1, types.NewString(string(buf))); err != nil {
+		return err
+	}
+	return vm.setOperandValue(frame, instr.Result, types.NewString(char[:1]))
+}
+
+// stringOffsetIndex converts the key of a string offset to an integer.
+// Integer strings are used as they are and leading-integer ones ("1x")
+// by their number with a warning, like floats, bools and null; other
+// strings, arrays and objects cannot be string offsets.
+func (vm *VM) stringOffsetIndex(key *types.Value) (int64, error) {
+	key = key.Deref()
+	switch key.Type() {
+	case types.TypeInt:
+		return key.ToInt(), nil
+	case types.TypeString:
+		number, kind := types.ParseNumeric(key.ToString())
+		if kind == types.NotNumeric || !number.IsInt() {
+			return 0, vm.ThrowError("TypeError", "Cannot access offset of type %s on string", key.TypeName())
+		}
+		if kind == types.LeadingNumeric {
+			vm.warning("Illegal string offset %s", arrayKeyLabel(key))
+		}
+		return number.ToInt(), nil
+	case types.TypeUndef, types.TypeNull, types.TypeBool, types.TypeFloat:
+		vm.warning("String offset cast occurred")
+		return key.ToInt(), nil
+	}
+	return 0, vm.ThrowError("TypeError", "Cannot access offset of type %s on string", key.TypeName())
+}
+
 // ============================================================================
 // Unset Operations
 // ============================================================================
@@ -311,9 +542,23 @@ func (vm *VM) opUnsetDim(frame *Frame, instr Instruction) error {
 	if err != nil {
 		return err
 	}
+	container = container.Deref()
 
-	if container.Type() != types.TypeArray {
-		// Unset on non-array is a no-op in PHP
+	switch container.Type() {
+	case types.TypeString:
+		return vm.ThrowError("Error", "Cannot unset string offsets")
+	case types.TypeObject:
+		key, err := vm.getOperandValue(frame, instr.Op2)
+		if err != nil {
+			return err
+		}
+		if _, called, err := vm.callMagic(container.ToObject(), "offsetUnset", key); called || err != nil {
+			return err
+		}
+		return vm.ThrowError("Error", "Cannot use object of type %s as array", container.ToObject().ClassName)
+	case types.TypeArray:
+	default:
+		// Unset on other non-arrays is a no-op in PHP
 		return nil
 	}
 
@@ -334,43 +579,78 @@ func (vm *VM) opUnsetDim(frame *Frame, instr Instruction) error {
 // Isset/Empty Operations
 // ============================================================================
 
-// opIssetIsemptyDimObj handles isset/empty check on array element
-// OpIssetIsemptyDimObj - Check isset/empty on array element or object property
+// opIssetIsemptyDimObj handles isset($a[$k]) and empty($a[$k])
+// Op1: container, Op2: key, ExtendedValue: IssetIsEmpty for empty()
+// Arrays are checked for the key, strings for the offset, and objects
+// implementing ArrayAccess through offsetExists() (and offsetGet() for
+// empty()); anything else is not set.
 func (vm *VM) opIssetIsemptyDimObj(frame *Frame, instr Instruction) error {
-	// Get the container (array or object)
 	container, err := vm.getOperandValue(frame, instr.Op1)
 	if err != nil {
 		return err
 	}
+	container = container.Deref()
 
-	// Get the key
 	key, err := vm.getOperandValue(frame, instr.Op2)
 	if err != nil {
 		return err
 	}
 
-	var result bool
+	var value *types.Value
+	exists := false
 
 	switch container.Type() {
 	case types.TypeArray:
-		arr := container.ToArray()
-		val, exists := arr.Get(key)
+		value, exists = container.ToArray().Get(key)
 
-		// For isset: check if exists and not null
-		// For empty: check if exists and is "empty" (falsy)
-		// TODO: Determine from instruction if this is isset or empty check
-		// For now, implement isset semantics
-		result = exists && !val.IsNull()
+	case types.TypeString:
+		value, exists = stringOffset(container.ToString(), key)
 
 	case types.TypeObject:
-		// TODO: Implement object property isset check in Phase 5
-		result = false
+		obj := container.ToObject()
+		result, called, err := vm.callMagic(obj, "offsetExists", key)
+		if err != nil {
+			return err
+		}
+		if called && result.ToBool() {
+			exists = true
+			value = types.NewBool(true)
+			if instr.ExtendedValue&IssetIsEmpty != 0 {
+				if value, _, err = vm.callMagic(obj, "offsetGet", key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return vm.setOperandValue(frame, instr.Result, issetOrEmpty(value, exists, instr))
+}
 
+// stringOffset returns the character at an integer offset of a string,
+// counting from the end for negative offsets; keys that are not integers
+// or canonical integer strings are never set
+func stringOffset(str string, key *types.Value) (*types.Value, bool) {
+	var index int64
+	switch key.Type() {
+	case types.TypeInt:
+		index = key.ToInt()
+	case types.TypeString:
+		parsed, err := strconv.ParseInt(key.ToString(), 10, 64)
+		if err != nil || strconv.FormatInt(parsed, 10) != key.ToString() {
+			return nil, false
+		}
+		index = parsed
 	default:
-		result = false
+		return nil, false
 	}
 
-	return vm.setOperandValue(frame, instr.Result, types.NewBool(result))
+	if index < 0 {
+		index += int64(len(str))
+	}
+	if index < 0 || index >= int64(len(str)) {
+		return nil, false
+	}
+	return types.NewString(str[index : index+1]), true
 }
 
 // ============================================================================
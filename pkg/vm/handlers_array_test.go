@@ -0,0 +1,128 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// runDim executes a dim instruction with the container in CV 0, the key in
+// CV 1 and the value or result in temp 2, returning the frame
+func runDim(vm *VM, opcode Opcode, container, key, value *types.Value) (*Frame, error) {
+	frame := NewFrame(&CompiledFunction{Name: "f", NumLocals: 3})
+	frame.setLocal(0, container)
+	frame.setLocal(1, key)
+	frame.setLocal(2, value)
+	instr := Instruction{Opcode: opcode, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpCV, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 2}}
+	return frame, vm.dispatchOpcode(frame, instr)
+}
+
+func TestStringOffset_Read(t *testing.T) {
+	tests := []struct {
+		key      *types.Value
+		expected string
+		warning  string
+	}{
+		{types.NewInt(3), "d", ""},
+		{types.NewInt(-1), "f", ""},
+		{types.NewString("2"), "c", ""},
+		{types.NewInt(10), "", "Warning: Uninitialized string offset 10"},
+		{types.NewInt(-7), "", "Warning: Uninitialized string offset -7"},
+		{types.NewString("1x"), "b", `Warning: Illegal string offset "1x"`},
+		{types.NewFloat(1.7), "b", "Warning: String offset cast occurred"},
+	}
+	for _, tt := range tests {
+		vm := New()
+		frame, err := runDim(vm, OpFetchDimR, types.NewString("abcdef"), tt.key, types.NewNull())
+		if err != nil {
+			t.Fatalf("$str[%v] failed: %v", tt.key, err)
+		}
+		if result := frame.getLocal(2); result.ToString() != tt.expected {
+			t.Errorf("$str[%v] = %q, want %q", tt.key, result.ToString(), tt.expected)
+		}
+		if output := vm.GetOutput(); (tt.warning == "") != (output == "") || !strings.Contains(output, tt.warning) {
+			t.Errorf("$str[%v]: expected warning %q, got %q", tt.key, tt.warning, output)
+		}
+	}
+
+	_, err := runDim(New(), OpFetchDimR, types.NewString("abc"), types.NewString("x"), types.NewNull())
+	expectThrown(t, err, "TypeError", "Cannot access offset of type string on string")
+}
+
+func TestStringOffset_Write(t *testing.T) {
+	tests := []struct {
+		str      string
+		key      *types.Value
+		value    *types.Value
+		expected string
+		result   *types.Value
+		warning  string
+	}{
+		{"abc", types.NewInt(1), types.NewString("x"), "axc", types.NewString("x"), ""},
+		{"abc", types.NewInt(-1), types.NewString("z"), "abz", types.NewString("z"), ""},
+		{"abc", types.NewInt(5), types.NewString("!"), "abc  !", types.NewString("!"), ""},
+		{"", types.NewInt(2), types.NewInt(7), "  7", types.NewString("7"), ""},
+		{"abc", types.NewInt(0), types.NewString("xyz"), "xbc", types.NewString("x"), "Warning: Only the first byte will be assigned to the string offset"},
+		{"abc", types.NewInt(-4), types.NewString("x"), "abc", types.NewNull(), "Warning: Illegal string offset -4"},
+	}
+	for _, tt := range tests {
+		vm := New()
+		frame, err := runDim(vm, OpAssignDim, types.NewString(tt.str), tt.key, tt.value)
+		if err != nil {
+			t.Fatalf("$str[%v] = %v failed: %v", tt.key, tt.value, err)
+		}
+		if got := frame.getLocal(0); !got.Identical(types.NewString(tt.expected)) {
+			t.Errorf("%q[%v] = %v: got %v, want %q", tt.str, tt.key, tt.value, got, tt.expected)
+		}
+		if result := frame.getLocal(2); !result.Identical(tt.result) {
+			t.Errorf("%q[%v] = %v: result %v, want %v", tt.str, tt.key, tt.value, result, tt.result)
+		}
+		if output := vm.GetOutput(); (tt.warning == "") != (output == "") || !strings.Contains(output, tt.warning) {
+			t.Errorf("%q[%v] = %v: expected warning %q, got %q", tt.str, tt.key, tt.value, tt.warning, output)
+		}
+	}
+
+	// Strings bound to a reference are written through
+	ref := types.NewReference(types.NewString("abc"))
+	frame, err := runDim(New(), OpAssignDim, ref, types.NewInt(0), types.NewString("A"))
+	if err != nil || ref.Deref().ToString() != "Abc" || !frame.getLocal(0).IsReference() {
+		t.Errorf("Expected the referenced string to become Abc, got %v (%v)", ref.Deref(), err)
+	}
+}
+
+func TestStringOffset_Errors(t *testing.T) {
+	_, err := runDim(New(), OpAssignDim, types.NewString("abc"), types.NewInt(0), types.NewString(""))
+	expectThrown(t, err, "Error", "Cannot assign an empty string to a string offset")
+
+	_, err = runDim(New(), OpAssignDim, types.NewString("abc"), types.NewArray(types.NewEmptyArray()), types.NewString("x"))
+	expectThrown(t, err, "TypeError", "Cannot access offset of type array on string")
+
+	_, err = runDim(New(), OpAssignDim, types.NewInt(5), types.NewInt(0), types.NewString("x"))
+	expectThrown(t, err, "Error", "Cannot use a scalar value as an array")
+
+	frame := NewFrame(&CompiledFunction{Name: "f", NumLocals: 3})
+	frame.setLocal(0, types.NewString("abc"))
+	frame.setLocal(2, types.NewString("x"))
+	err = New().dispatchOpcode(frame, Instruction{Opcode: OpAssignDim, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpUnused}, Result: Operand{Type: OpTmpVar, Value: 2}})
+	expectThrown(t, err, "Error", "[] operator not supported for strings")
+
+	frame = NewFrame(&CompiledFunction{Name: "f", NumLocals: 3})
+	frame.setLocal(0, types.NewString("abc"))
+	frame.setLocal(1, types.NewInt(0))
+	frame.setLocal(2, types.NewString("x"))
+	err = New().dispatchOpcode(frame, Instruction{Opcode: OpAssignDimOp, ExtendedValue: uint32(OpConcat), Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpCV, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 2}})
+	expectThrown(t, err, "Error", "Cannot use assign-op operators with string offsets")
+}
+
+func TestStringOffset_Isset(t *testing.T) {
+	for key, expected := range map[int64]string{1: "b", -1: "c", 3: ""} {
+		frame, err := runDim(New(), OpFetchDimIs, types.NewString("abc"), types.NewInt(key), types.NewNull())
+		if err != nil {
+			t.Fatalf("isset($str[%d]) failed: %v", key, err)
+		}
+		if result := frame.getLocal(2); (expected == "" && !result.IsNull()) || (expected != "" && result.ToString() != expected) {
+			t.Errorf("$str[%d] ?? null = %v, want %q", key, result, expected)
+		}
+	}
+}
@@ -8,36 +8,20 @@ import "github.com/krizos/php-go/pkg/types"
 
 // opIsEqual handles loose equality (==)
 func (vm *VM) opIsEqual(frame *Frame, instr Instruction) error {
-	left, err := vm.getOperandValue(frame, instr.Op1)
+	result, err := vm.compareOperands(frame, instr)
 	if err != nil {
 		return err
 	}
-
-	right, err := vm.getOperandValue(frame, instr.Op2)
-	if err != nil {
-		return err
-	}
-
-	result := types.NewBool(left.Equals(right))
-
-	return vm.setOperandValue(frame, instr.Result, result)
+	return vm.setOperandValue(frame, instr.Result, types.NewBool(result == 0))
 }
 
 // opIsNotEqual handles loose inequality (!=)
 func (vm *VM) opIsNotEqual(frame *Frame, instr Instruction) error {
-	left, err := vm.getOperandValue(frame, instr.Op1)
+	result, err := vm.compareOperands(frame, instr)
 	if err != nil {
 		return err
 	}
-
-	right, err := vm.getOperandValue(frame, instr.Op2)
-	if err != nil {
-		return err
-	}
-
-	result := types.NewBool(!left.Equals(right))
-
-	return vm.setOperandValue(frame, instr.Result, result)
+	return vm.setOperandValue(frame, instr.Result, types.NewBool(result != 0))
 }
 
 // opIsIdentical handles strict equality (===)
@@ -52,7 +36,7 @@ func (vm *VM) opIsIdentical(frame *Frame, instr Instruction) error {
 		return err
 	}
 
-	result := types.NewBool(left.Identical(right))
+	result := types.NewBool(types.StrictEquals(left, right))
 
 	return vm.setOperandValue(frame, instr.Result, result)
 }
@@ -69,96 +53,77 @@ func (vm *VM) opIsNotIdentical(frame *Frame, instr Instruction) error {
 		return err
 	}
 
-	result := types.NewBool(!left.Identical(right))
+	result := types.NewBool(!types.StrictEquals(left, right))
 
 	return vm.setOperandValue(frame, instr.Result, result)
 }
 
 // opIsSmaller handles less than (<)
 func (vm *VM) opIsSmaller(frame *Frame, instr Instruction) error {
-	left, err := vm.getOperandValue(frame, instr.Op1)
+	result, err := vm.compareOperands(frame, instr)
 	if err != nil {
 		return err
 	}
-
-	right, err := vm.getOperandValue(frame, instr.Op2)
-	if err != nil {
-		return err
-	}
-
-	// PHP comparison: convert to numbers if needed
-	var result bool
-
-	if left.IsString() && right.IsString() {
-		// String comparison
-		result = left.ToString() < right.ToString()
-	} else {
-		// Numeric comparison
-		result = left.ToFloat() < right.ToFloat()
-	}
-
-	return vm.setOperandValue(frame, instr.Result, types.NewBool(result))
+	return vm.setOperandValue(frame, instr.Result, types.NewBool(result < 0))
 }
 
 // opIsSmallerOrEqual handles less than or equal (<=)
 func (vm *VM) opIsSmallerOrEqual(frame *Frame, instr Instruction) error {
-	left, err := vm.getOperandValue(frame, instr.Op1)
+	result, err := vm.compareOperands(frame, instr)
 	if err != nil {
 		return err
 	}
+	return vm.setOperandValue(frame, instr.Result, types.NewBool(result <= 0))
+}
 
-	right, err := vm.getOperandValue(frame, instr.Op2)
+// opSpaceship handles spaceship operator (<=>)
+// Returns -1 if left < right, 0 if equal, 1 if left > right
+func (vm *VM) opSpaceship(frame *Frame, instr Instruction) error {
+	result, err := vm.compareOperands(frame, instr)
 	if err != nil {
 		return err
 	}
-
-	var result bool
-
-	if left.IsString() && right.IsString() {
-		result = left.ToString() <= right.ToString()
-	} else {
-		result = left.ToFloat() <= right.ToFloat()
-	}
-
-	return vm.setOperandValue(frame, instr.Result, types.NewBool(result))
+	return vm.setOperandValue(frame, instr.Result, types.NewInt(int64(result)))
 }
 
-// opSpaceship handles spaceship operator (<=>)
-// Returns -1 if left < right, 0 if equal, 1 if left > right
-func (vm *VM) opSpaceship(frame *Frame, instr Instruction) error {
+// compareOperands compares the two operands of a comparison instruction
+func (vm *VM) compareOperands(frame *Frame, instr Instruction) (int, error) {
 	left, err := vm.getOperandValue(frame, instr.Op1)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	right, err := vm.getOperandValue(frame, instr.Op2)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	var result int64
-
-	if left.IsString() && right.IsString() {
-		leftStr := left.ToString()
-		rightStr := right.ToString()
-		if leftStr < rightStr {
-			result = -1
-		} else if leftStr > rightStr {
-			result = 1
-		} else {
-			result = 0
-		}
-	} else {
-		leftNum := left.ToFloat()
-		rightNum := right.ToFloat()
-		if leftNum < rightNum {
-			result = -1
-		} else if leftNum > rightNum {
-			result = 1
-		} else {
-			result = 0
-		}
+	return vm.compare(left, right)
+}
+
+// compare compares two values with PHP's loose comparison (see
+// types.Compare), converting an object compared with a string to string
+// first if it implements __toString()
+func (vm *VM) compare(left, right *types.Value) (int, error) {
+	left, right = left.Deref(), right.Deref()
+	var err error
+	if left.IsObject() && right.IsString() {
+		left, err = vm.comparedString(left)
+	} else if left.IsString() && right.IsObject() {
+		right, err = vm.comparedString(right)
+	}
+	if err != nil {
+		return 0, err
 	}
+	return types.Compare(left, right), nil
+}
 
-	return vm.setOperandValue(frame, instr.Result, types.NewInt(result))
+// comparedString returns the string an object compares as with a string,
+// or the object itself if it is not Stringable
+func (vm *VM) comparedString(object *types.Value) (*types.Value, error) {
+	s, ok, err := vm.stringable(object)
+	if err != nil || !ok {
+		return object, err
+	}
+	return types.NewString(s), nil
 }
@@ -0,0 +1,70 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// runComparison executes a comparison instruction on two values and
+// returns its result
+func runComparison(t *testing.T, vm *VM, opcode Opcode, left, right *types.Value) *types.Value {
+	t.Helper()
+	frame := NewFrame(&CompiledFunction{Name: "f", NumLocals: 3})
+	frame.setLocal(0, left)
+	frame.setLocal(1, right)
+	instr := Instruction{Opcode: opcode, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpCV, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 2}}
+	if err := vm.dispatchOpcode(frame, instr); err != nil {
+		t.Fatalf("%s failed: %v", opcode, err)
+	}
+	result, err := vm.getOperandValue(frame, instr.Result)
+	if err != nil {
+		t.Fatalf("%s result: %v", opcode, err)
+	}
+	return result
+}
+
+func TestComparison_LooseAndStrict(t *testing.T) {
+	vm := New()
+	tests := []struct {
+		opcode      Opcode
+		left, right *types.Value
+		expected    *types.Value
+	}{
+		{OpIsEqual, types.NewInt(0), types.NewString("a"), types.NewBool(false)},
+		{OpIsEqual, types.NewString("1e3"), types.NewString("1000"), types.NewBool(true)},
+		{OpIsNotEqual, types.NewNull(), types.NewBool(false), types.NewBool(false)},
+		{OpIsIdentical, types.NewInt(1), types.NewFloat(1), types.NewBool(false)},
+		{OpIsNotIdentical, types.NewString("a"), types.NewString("a"), types.NewBool(false)},
+		{OpIsSmaller, types.NewString("10"), types.NewString("9"), types.NewBool(false)},
+		{OpIsSmaller, types.NewString("abc"), types.NewString("abd"), types.NewBool(true)},
+		{OpIsSmallerOrEqual, types.NewNull(), types.NewInt(-1), types.NewBool(true)},
+		{OpSpaceship, types.NewInt(2), types.NewString("10"), types.NewInt(-1)},
+		{OpSpaceship, types.NewArray(types.NewEmptyArray()), types.NewInt(5), types.NewInt(1)},
+	}
+	for _, tt := range tests {
+		if result := runComparison(t, vm, tt.opcode, tt.left, tt.right); !result.Identical(tt.expected) {
+			t.Errorf("%s %v, %v = %v, want %v", tt.opcode, tt.left, tt.right, result, tt.expected)
+		}
+	}
+}
+
+func TestComparison_StringableObject(t *testing.T) {
+	vm := New()
+	class := types.NewClassEntry("Name")
+	addNativeMethod(class, "__toString", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		return types.NewString("name"), nil
+	})
+	vm.classes["Name"] = class
+	obj := types.NewObject(types.NewObjectFromClass(class))
+
+	if result := runComparison(t, vm, OpIsEqual, obj, types.NewString("name")); !result.ToBool() {
+		t.Error("Expected a Stringable object to equal its string")
+	}
+	if result := runComparison(t, vm, OpSpaceship, types.NewString("abc"), obj); result.ToInt() != -1 {
+		t.Errorf("\"abc\" <=> Name = %v, want -1", result)
+	}
+	if result := runComparison(t, vm, OpIsEqual, types.NewObject(types.NewObjectInstance("Plain")), types.NewString("name")); result.ToBool() {
+		t.Error("Expected an object without __toString() not to equal a string")
+	}
+}
@@ -28,7 +28,7 @@ func TestConstructor_BasicInvocation(t *testing.T) {
 			},
 			// Fetch constant "initialized"
 			Instruction{
-				Opcode: OpFetchConstant,
+				Opcode: OpQMAssign,
 				Op1:    Operand{Type: OpConst, Value: 0}, // "initialized"
 				Result: Operand{Type: OpTmpVar, Value: 2},
 			},
@@ -392,7 +392,7 @@ func TestFunctionCall_Basic(t *testing.T) {
 		Name: "greet",
 		Instructions: Instructions{
 			{
-				Opcode: OpFetchConstant,
+				Opcode: OpQMAssign,
 				Op1:    Operand{Type: OpConst, Value: 0}, // "Hello"
 				Result: Operand{Type: OpTmpVar, Value: 0},
 			},
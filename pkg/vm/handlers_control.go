@@ -1,5 +1,13 @@
 package vm
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
 // ============================================================================
 // Control Flow Opcode Handlers
 // ============================================================================
@@ -47,3 +55,258 @@ func (vm *VM) opJmpNZ(frame *Frame, instr Instruction) error {
 
 	return nil
 }
+
+// opCoalesce handles the null coalescing operator: result = op1 ?? op2
+// If Op1 is neither null nor undefined, it becomes the result and execution
+// jumps to Op2, skipping the right operand; otherwise execution falls
+// through to the code computing the right operand.
+func (vm *VM) opCoalesce(frame *Frame, instr Instruction) error {
+	value, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	value = value.Deref()
+	if value.IsNull() || value.IsUndef() {
+		return nil
+	}
+	frame.ip = int(instr.Op2.Value)
+	return vm.setOperandValue(frame, instr.Result, assignValue(value))
+}
+
+// Chains short-circuited by JMP_NULL evaluate to null, except under
+// isset() and empty(), which the ExtendedValue of JMP_NULL marks
+const (
+	JmpNullIsset uint32 = 1 // isset($a?->b) is false
+	JmpNullEmpty uint32 = 2 // empty($a?->b) is true
+)
+
+// opJmpNull short-circuits a nullsafe chain: if Op1 is null, the result of
+// the whole chain is null (see JmpNullIsset) and execution jumps to Op2,
+// the end of the chain
+func (vm *VM) opJmpNull(frame *Frame, instr Instruction) error {
+	value, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	value = value.Deref()
+	if !value.IsNull() && !value.IsUndef() {
+		return nil
+	}
+	frame.ip = int(instr.Op2.Value)
+
+	result := types.NewNull()
+	switch instr.ExtendedValue {
+	case JmpNullIsset:
+		result = types.NewBool(false)
+	case JmpNullEmpty:
+		result = types.NewBool(true)
+	}
+	return vm.setOperandValue(frame, instr.Result, result)
+}
+
+// ============================================================================
+// Switch and Match Handlers
+// ============================================================================
+
+// JumpTable maps the literal conditions of a switch or match to the
+// instructions of their bodies. It is stored in the constant table and
+// referenced by OpSwitchLong, OpSwitchString and OpMatch.
+type JumpTable struct {
+	Longs   map[int64]int
+	Strings map[string]int
+	// Default is the target when the subject has the table's type but no
+	// entry (the default body, the MATCH_ERROR instruction, or the end)
+	Default int
+}
+
+// NewJumpTable creates an empty jump table
+func NewJumpTable() *JumpTable {
+	return &JumpTable{Longs: make(map[int64]int), Strings: make(map[string]int)}
+}
+
+// Add registers a target for a literal condition. Earlier conditions win,
+// as only the first matching arm or case is ever taken.
+func (t *JumpTable) Add(condition interface{}, target int) {
+	switch c := condition.(type) {
+	case int64:
+		if _, ok := t.Longs[c]; !ok {
+			t.Longs[c] = target
+		}
+	case string:
+		if _, ok := t.Strings[c]; !ok {
+			t.Strings[c] = target
+		}
+	}
+}
+
+// Retarget rewrites every target equal to from, so the compiler can patch
+// placeholder targets once the bodies have been emitted
+func (t *JumpTable) Retarget(from, to int) {
+	for key, target := range t.Longs {
+		if target == from {
+			t.Longs[key] = to
+		}
+	}
+	for key, target := range t.Strings {
+		if target == from {
+			t.Strings[key] = to
+		}
+	}
+	if t.Default == from {
+		t.Default = to
+	}
+}
+
+// jumpTable fetches the jump table referenced by an operand
+func (vm *VM) jumpTable(frame *Frame, op Operand) (*JumpTable, error) {
+	constant, ok := vm.constantOperand(frame, op)
+	if !ok {
+		return nil, fmt.Errorf("invalid jump table operand")
+	}
+	table, ok := constant.(*JumpTable)
+	if !ok {
+		return nil, fmt.Errorf("constant %d is not a jump table", op.Value)
+	}
+	return table, nil
+}
+
+// opSwitchLong handles switch on integer case labels
+// Op1: subject, Op2: jump table constant
+// Non-integer subjects fall through to the IS_EQUAL chain that follows,
+// which implements the loose comparison.
+func (vm *VM) opSwitchLong(frame *Frame, instr Instruction) error {
+	subject, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	table, err := vm.jumpTable(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+
+	subject = subject.Deref()
+	if subject.Type() != types.TypeInt {
+		return nil
+	}
+	if target, ok := table.Longs[subject.ToInt()]; ok {
+		frame.ip = target
+	} else {
+		frame.ip = table.Default
+	}
+	return nil
+}
+
+// opSwitchString handles switch on non-numeric string case labels
+// Op1: subject, Op2: jump table constant
+// Non-string subjects fall through to the IS_EQUAL chain that follows.
+func (vm *VM) opSwitchString(frame *Frame, instr Instruction) error {
+	subject, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	table, err := vm.jumpTable(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+
+	subject = subject.Deref()
+	if subject.Type() != types.TypeString {
+		return nil
+	}
+	if target, ok := table.Strings[subject.ToString()]; ok {
+		frame.ip = target
+	} else {
+		frame.ip = table.Default
+	}
+	return nil
+}
+
+// opMatch dispatches a match expression whose conditions are all integer
+// or string literals. Comparison is strict, so any subject without an
+// entry goes to the default target.
+// Op1: subject, Op2: jump table constant
+func (vm *VM) opMatch(frame *Frame, instr Instruction) error {
+	subject, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	table, err := vm.jumpTable(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+
+	frame.ip = table.Default
+	subject = subject.Deref()
+	switch subject.Type() {
+	case types.TypeInt:
+		if target, ok := table.Longs[subject.ToInt()]; ok {
+			frame.ip = target
+		}
+	case types.TypeString:
+		if target, ok := table.Strings[subject.ToString()]; ok {
+			frame.ip = target
+		}
+	}
+	return nil
+}
+
+// opCaseStrict compares a match subject with one arm condition (===)
+// Op1: subject, Op2: condition, Result: bool
+func (vm *VM) opCaseStrict(frame *Frame, instr Instruction) error {
+	subject, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	condition, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+
+	result := types.NewBool(subject.Deref().Identical(condition.Deref()))
+	return vm.setOperandValue(frame, instr.Result, result)
+}
+
+// opMatchError throws UnhandledMatchError for a subject no arm matched
+// Op1: subject
+func (vm *VM) opMatchError(frame *Frame, instr Instruction) error {
+	subject, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	return vm.ThrowError("UnhandledMatchError", "Unhandled match case %s", describeMatchSubject(subject.Deref()))
+}
+
+// describeMatchSubject renders a subject for the UnhandledMatchError
+// message: scalars are shown as values, strings quoted and truncated to
+// 15 bytes, everything else by type
+func describeMatchSubject(subject *types.Value) string {
+	switch subject.Type() {
+	case types.TypeNull:
+		return "NULL"
+	case types.TypeBool:
+		if subject.ToBool() {
+			return "true"
+		}
+		return "false"
+	case types.TypeInt:
+		return strconv.FormatInt(subject.ToInt(), 10)
+	case types.TypeFloat:
+		s := types.FormatFloat(subject.ToFloat(), types.SerializePrecision())
+		if !strings.ContainsAny(s, ".EN") {
+			s += ".0"
+		}
+		return s
+	case types.TypeString:
+		s := subject.ToString()
+		suffix := ""
+		if len(s) > 15 {
+			s, suffix = s[:15], "..."
+		}
+		s = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+		return "'" + s + "'" + suffix
+	case types.TypeObject:
+		return "of type " + subject.ToObject().ClassName
+	default:
+		return "of type " + subject.TypeString()
+	}
+}
@@ -0,0 +1,237 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// matchProgram builds:
+//
+//	$r = match ($subject) { "1" => "string", 1, 2 => "int" };
+//
+// with CASE_STRICT comparisons and MATCH_ERROR when nothing matches.
+// Constants: 0 "1", 1 int 1, 2 int 2, 3 "string", 4 "int"
+func matchProgram() Instructions {
+	cv := func(i uint32) Operand { return Operand{Type: OpCV, Value: i} }
+	constant := func(i uint32) Operand { return Operand{Type: OpConst, Value: i} }
+	tmp := Operand{Type: OpTmpVar, Value: 2}
+	return Instructions{
+		{Opcode: OpCaseStrict, Op1: cv(0), Op2: constant(0), Result: tmp}, // 0
+		{Opcode: OpJmpNZ, Op1: tmp, Op2: Operand{Value: 7}},
+		{Opcode: OpCaseStrict, Op1: cv(0), Op2: constant(1), Result: tmp},
+		{Opcode: OpJmpNZ, Op1: tmp, Op2: Operand{Value: 9}},
+		{Opcode: OpCaseStrict, Op1: cv(0), Op2: constant(2), Result: tmp},
+		{Opcode: OpJmpNZ, Op1: tmp, Op2: Operand{Value: 9}},
+		{Opcode: OpJmp, Op1: Operand{Value: 11}},
+		{Opcode: OpAssign, Op2: constant(3), Result: cv(1)}, // 7
+		{Opcode: OpJmp, Op1: Operand{Value: 12}},
+		{Opcode: OpAssign, Op2: constant(4), Result: cv(1)}, // 9
+		{Opcode: OpJmp, Op1: Operand{Value: 12}},
+		{Opcode: OpMatchError, Op1: cv(0)}, // 11
+	}
+}
+
+func runMatch(t *testing.T, instrs Instructions, constants []interface{}, subject *types.Value) (*types.Value, error) {
+	t.Helper()
+	vm := New()
+	vm.constants = constants
+	frame := NewFrame(&CompiledFunction{Name: "main", Instructions: instrs, NumLocals: 10})
+	frame.setLocal(0, subject)
+	vm.pushFrame(frame)
+	err := vm.runFrame(frame)
+	return frame.getLocal(1), err
+}
+
+func TestMatch_StrictArms(t *testing.T) {
+	constants := []interface{}{"1", int64(1), int64(2), "string", "int"}
+	tests := []struct {
+		subject *types.Value
+		want    string
+	}{
+		{types.NewString("1"), "string"},
+		{types.NewInt(1), "int"},
+		{types.NewInt(2), "int"},
+	}
+
+	for _, tt := range tests {
+		result, err := runMatch(t, matchProgram(), constants, tt.subject)
+		if err != nil {
+			t.Fatalf("match(%v) failed: %v", tt.subject, err)
+		}
+		if result.ToString() != tt.want {
+			t.Errorf("match(%v) = %q, want %q", tt.subject, result.ToString(), tt.want)
+		}
+	}
+}
+
+func TestMatch_UnhandledMatchError(t *testing.T) {
+	constants := []interface{}{"1", int64(1), int64(2), "string", "int"}
+	tests := []struct {
+		subject *types.Value
+		message string
+	}{
+		{types.NewFloat(1), "Unhandled match case 1.0"},
+		{types.NewBool(true), "Unhandled match case true"},
+		{types.NewNull(), "Unhandled match case NULL"},
+		{types.NewInt(3), "Unhandled match case 3"},
+		{types.NewString("it's a long string"), `Unhandled match case 'it\'s a long str'...`},
+		{types.NewArray(types.NewEmptyArray()), "Unhandled match case of type array"},
+	}
+
+	for _, tt := range tests {
+		_, err := runMatch(t, matchProgram(), constants, tt.subject)
+		throwable, ok := err.(*ThrowableError)
+		if !ok || throwable.Object.ClassEntry.Name != "UnhandledMatchError" {
+			t.Fatalf("match(%v): expected UnhandledMatchError, got %v", tt.subject, err)
+		}
+		if msg := throwableProperty(throwable.Object, "message").ToString(); msg != tt.message {
+			t.Errorf("match(%v) message = %q, want %q", tt.subject, msg, tt.message)
+		}
+	}
+}
+
+func TestMatch_JumpTable(t *testing.T) {
+	table := NewJumpTable()
+	table.Add(int64(1), 2)
+	table.Add("a", 4)
+	table.Add(int64(1), 99) // later duplicates never win
+	table.Default = 6
+
+	// MATCH jumps to 2 ("one"), 4 ("a") or 6 (MATCH_ERROR)
+	instrs := Instructions{
+		{Opcode: OpMatch, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 0}},
+		{Opcode: OpNop},
+		{Opcode: OpAssign, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpCV, Value: 1}}, // 2
+		{Opcode: OpJmp, Op1: Operand{Value: 7}},
+		{Opcode: OpAssign, Op2: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpCV, Value: 1}}, // 4
+		{Opcode: OpJmp, Op1: Operand{Value: 7}},
+		{Opcode: OpMatchError, Op1: Operand{Type: OpCV, Value: 0}}, // 6
+	}
+	constants := []interface{}{table, "one", "a"}
+
+	if result, err := runMatch(t, instrs, constants, types.NewInt(1)); err != nil || result.ToString() != "one" {
+		t.Errorf("match(1) = %v, %v", result, err)
+	}
+	if result, err := runMatch(t, instrs, constants, types.NewString("a")); err != nil || result.ToString() != "a" {
+		t.Errorf("match('a') = %v, %v", result, err)
+	}
+	// Strict: "1" has no entry even though 1 does
+	if _, err := runMatch(t, instrs, constants, types.NewString("1")); err == nil {
+		t.Error("match('1') should throw UnhandledMatchError")
+	}
+}
+
+func TestSwitchLong_FallsThroughForOtherTypes(t *testing.T) {
+	table := NewJumpTable()
+	table.Add(int64(1), 3)
+	table.Default = 4
+
+	// SWITCH_LONG; non-integers reach the IS_EQUAL chain at 1
+	instrs := Instructions{
+		{Opcode: OpSwitchLong, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 0}},
+		{Opcode: OpIsEqual, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 2}},
+		{Opcode: OpJmpNZ, Op1: Operand{Type: OpTmpVar, Value: 2}, Op2: Operand{Value: 3}},
+		{Opcode: OpAssign, Op2: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpCV, Value: 1}}, // 3
+		{Opcode: OpNop}, // 4
+	}
+	constants := []interface{}{table, int64(1), "case 1"}
+
+	for _, subject := range []*types.Value{types.NewInt(1), types.NewFloat(1), types.NewString("1")} {
+		if result, err := runMatch(t, instrs, constants, subject); err != nil || result.ToString() != "case 1" {
+			t.Errorf("switch(%v) = %v, %v; want case 1", subject, result, err)
+		}
+	}
+	if result, _ := runMatch(t, instrs, constants, types.NewInt(2)); !result.IsNull() {
+		t.Errorf("switch(2) should skip to the default target, got %v", result)
+	}
+}
+
+func TestSwitchString_JumpsByLabel(t *testing.T) {
+	table := NewJumpTable()
+	table.Add("a", 2)
+	table.Add("b", 3)
+	table.Default = 4
+
+	instrs := Instructions{
+		{Opcode: OpSwitchString, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 0}},
+		{Opcode: OpNop},
+		{Opcode: OpAssign, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpCV, Value: 1}}, // 2: case "a" falls through
+		{Opcode: OpAssign, Op2: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpCV, Value: 1}}, // 3
+		{Opcode: OpNop},
+	}
+	constants := []interface{}{table, "A", "B"}
+
+	if result, _ := runMatch(t, instrs, constants, types.NewString("a")); result.ToString() != "B" {
+		t.Errorf("switch('a') = %v, want fall-through to B", result)
+	}
+	if result, _ := runMatch(t, instrs, constants, types.NewString("c")); !result.IsNull() {
+		t.Errorf("switch('c') should skip to the default target, got %v", result)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	cv := func(i uint32) Operand { return Operand{Type: OpCV, Value: i} }
+	tmp := Operand{Type: OpTmpVar, Value: 10}
+
+	// $r = $x ?? "default"
+	instrs := Instructions{
+		{Opcode: OpFetchIs, Op1: cv(0), Result: tmp},
+		{Opcode: OpCoalesce, Op1: tmp, Op2: Operand{Value: 3}, Result: tmp},
+		{Opcode: OpAssign, Op2: Operand{Type: OpConst, Value: 0}, Result: tmp},
+		{Opcode: OpAssign, Op2: tmp, Result: cv(1)}, // 3
+	}
+	tests := []struct {
+		subject *types.Value
+		want    string
+	}{
+		{types.NewUndef(), "default"},
+		{types.NewNull(), "default"},
+		{types.NewInt(0), "0"},
+		{types.NewReference(types.NewString("ref")), "ref"},
+	}
+
+	for _, tt := range tests {
+		vm := New()
+		vm.constants = []interface{}{"default"}
+		frame := NewFrame(&CompiledFunction{Name: "main", Instructions: instrs, NumLocals: 10, Variables: []string{"x", "r"}})
+		frame.setLocal(0, tt.subject)
+		vm.pushFrame(frame)
+		if err := vm.runFrame(frame); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := frame.getLocal(1); got.ToString() != tt.want || got.IsReference() {
+			t.Errorf("%v ?? \"default\" = %v, want %q", tt.subject, got, tt.want)
+		}
+		if vm.GetOutput() != "" {
+			t.Errorf("Expected no notice, got %q", vm.GetOutput())
+		}
+	}
+}
+
+func TestJmpNull(t *testing.T) {
+	cv := func(i uint32) Operand { return Operand{Type: OpCV, Value: i} }
+	tmp := Operand{Type: OpTmpVar, Value: 10}
+
+	// $r = $o?->p
+	instrs := Instructions{
+		{Opcode: OpJmpNull, Op1: cv(0), Op2: Operand{Value: 2}, Result: tmp},
+		{Opcode: OpFetchObjR, Op1: cv(0), Op2: Operand{Type: OpConst, Value: 0}, Result: tmp},
+		{Opcode: OpAssign, Op2: tmp, Result: cv(1)}, // 2
+	}
+	obj := types.NewObjectInstance("stdClass")
+	obj.SetProperty("p", types.NewInt(5), nil)
+
+	for _, subject := range []*types.Value{types.NewNull(), types.NewObject(obj)} {
+		result, err := runMatch(t, instrs, []interface{}{"p"}, subject)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if subject.IsNull() && !result.IsNull() {
+			t.Errorf("null?->p = %v, want null", result)
+		}
+		if !subject.IsNull() && result.ToInt() != 5 {
+			t.Errorf("$o?->p = %v, want 5", result)
+		}
+	}
+}
@@ -37,70 +37,110 @@ func (vm *VM) opInitFcall(frame *Frame, instr Instruction) error {
 		return err
 	}
 	funcNameStr := funcName.ToString()
+	frame.suspendPendingCall()
 
-	// Look up the function in VM's function registry
+	// Look up the function in VM's function registry, falling back to builtins
 	fn, exists := vm.GetFunction(funcNameStr)
 	if !exists {
-		return fmt.Errorf("Call to undefined function %s()", funcNameStr)
-	}
-
-	// Store pending function call info in frame
-	frame.pendingFunction = fn
-	frame.pendingParams = &CallParams{
-		params: make([]*types.Value, 0, int(instr.ExtendedValue)),
+		target, ok := vm.lookupFunction(funcNameStr)
+		if !ok {
+			return fmt.Errorf("Call to undefined function %s()", funcNameStr)
+		}
+		frame.pendingCall = target
+	} else {
+		// Store pending function call info in frame
+		frame.pendingFunction = fn
 	}
+	frame.pendingParams = vm.newCallParams(int(instr.ExtendedValue))
 
 	return nil
 }
 
 // opSendVal sends a parameter value for the pending function/method call
 // Op1: parameter value
+// Op2: parameter name for a named argument (unused for positional ones)
 func (vm *VM) opSendVal(frame *Frame, instr Instruction) error {
 	// Get the parameter value
 	paramValue, err := vm.getOperandValue(frame, instr.Op1)
 	if err != nil {
 		return err
 	}
+	return vm.sendArgument(frame, instr.Op2, assignValue(paramValue))
+}
 
-	// Add to pending parameters
+// sendArgument adds an argument to the pending call, by name when the
+// operand naming it is used
+func (vm *VM) sendArgument(frame *Frame, nameOp Operand, value *types.Value) error {
 	if frame.pendingParams == nil {
-		frame.pendingParams = &CallParams{
-			params: make([]*types.Value, 0, 8),
+		frame.pendingParams = vm.newCallParams(8)
+	}
+	if nameOp.Type != OpUnused {
+		name, err := vm.getOperandValue(frame, nameOp)
+		if err != nil {
+			return err
 		}
+		return vm.addNamedArgument(frame.pendingParams, name.ToString(), value)
 	}
-	frame.pendingParams.params = append(frame.pendingParams.params, paramValue)
-
+	frame.pendingParams.params = append(frame.pendingParams.params, value)
 	return nil
 }
 
+// CallTail marks a DO_FCALL whose result the function returns at once, a
+// call the optimizer found the function makes to itself. It runs in the
+// frame making it (see reuseFrame). The argument count DO_FCALL may carry
+// in its extended value stays below it.
+const CallTail uint32 = 1 << 31
+
 // opDoFcall executes a function or method call
 // This handles both regular function calls (from OpInitFcall) and method calls (from OpInitMethodCall)
+// A call suspended while this one was prepared becomes pending again.
 // Result: return value
+// ExtendedValue: CallTail for a self tail call
 func (vm *VM) opDoFcall(frame *Frame, instr Instruction) error {
 	var fn *CompiledFunction
 	var thisObj *types.Object
 	var currentClass *types.ClassEntry
 	var calledClass *types.ClassEntry
 
+	// Calls resolved from a callable value (builtins, closures, [$obj, 'm'])
+	// and methods of built-in classes implemented in Go
+	if frame.pendingCall != nil || (frame.pendingMethod != nil && frame.pendingMethod.Handler != nil) {
+		target, _ := vm.takePendingCall(frame)
+		callParams := frame.pendingParams
+		params, rest, err := vm.bindArguments(target.Name, target.parameters(), callParams)
+		frame.pendingParams = nil
+		frame.resumePendingCall()
+		if err != nil {
+			return err
+		}
+		target.NamedRest = rest
+
+		target.Strict = frame.fn.StrictTypes
+		returnValue, err := vm.invokeTarget(target, params)
+		if target.Builtin == nil {
+			vm.recycleCallParams(callParams)
+		}
+		if err != nil {
+			return err
+		}
+		if instr.Result.Type != OpUnused {
+			return vm.setOperandValue(frame, instr.Result, returnValue)
+		}
+		return nil
+	}
+
 	// Check if this is a method call or regular function call
 	if frame.pendingMethod != nil {
 		// Method call - convert MethodDef to CompiledFunction
-		fn = &CompiledFunction{
-			Name:         frame.pendingMethod.Name,
-			Instructions: convertInstructions(frame.pendingMethod.Instructions),
-			NumLocals:    frame.pendingMethod.NumLocals,
-			NumParams:    frame.pendingMethod.NumParams,
-		}
+		fn = methodToFunction(frame.pendingMethod)
 
-		thisObj = frame.pendingObject
-		if thisObj != nil && thisObj.ClassEntry != nil {
-			currentClass = thisObj.ClassEntry
-			calledClass = thisObj.ClassEntry
-		}
+		thisObj, currentClass, calledClass = vm.pendingMethodContext(frame)
 
 		// Clear pending method
 		frame.pendingMethod = nil
 		frame.pendingObject = nil
+		frame.pendingClass = nil
+		frame.pendingCalledClass = nil
 	} else if frame.pendingFunction != nil {
 		// Regular function call
 		fn = frame.pendingFunction
@@ -109,20 +149,32 @@ func (vm *VM) opDoFcall(frame *Frame, instr Instruction) error {
 		return fmt.Errorf("DO_FCALL: no pending function or method call")
 	}
 
-	// Get parameters
-	params := make([]*types.Value, 0)
-	if frame.pendingParams != nil {
-		params = frame.pendingParams.params
-		frame.pendingParams = nil
+	// Get parameters, with named arguments moved to their positions
+	callParams := frame.pendingParams
+	params, rest, err := vm.bindArguments(fn.Name, fn.Parameters, callParams)
+	frame.pendingParams = nil
+	frame.resumePendingCall()
+	if err != nil {
+		return err
+	}
+
+	// A self tail call runs in this frame rather than a new one
+	if instr.ExtendedValue&CallTail != 0 && vm.canReuseFrame(frame, fn, thisObj) {
+		vm.reuseFrame(frame, callParams, params, rest)
+		return nil
 	}
 
 	// Create new frame for the function/method
-	newFrame := NewFrame(fn)
+	newFrame := vm.newCallFrame(fn)
 
 	// Set object/class context for methods
 	newFrame.thisObject = thisObj
 	newFrame.currentClass = currentClass
 	newFrame.calledClass = calledClass
+	newFrame.args = params
+	newFrame.namedRest = rest
+	newFrame.callParams = callParams
+	newFrame.strictArgs = frame.fn.StrictTypes
 
 	// Copy parameters to the new frame's local variables
 	for i, param := range params {
@@ -135,18 +187,22 @@ func (vm *VM) opDoFcall(frame *Frame, instr Instruction) error {
 
 	// Push the new frame onto the call stack
 	if err := vm.pushFrame(newFrame); err != nil {
+		vm.releaseFrame(newFrame)
 		return err
 	}
 
 	// Execute the function immediately in this context
 	// The function will run until it returns or hits an error
-	err := vm.runFrame(newFrame)
+	err = vm.runFrame(newFrame)
 	if err != nil {
+		// Unwind the callee so the caller can handle the exception
+		vm.releaseFrame(vm.popFrame())
 		return err
 	}
 
 	// Pop the completed frame
 	completedFrame := vm.popFrame()
+	vm.releaseFrame(completedFrame)
 
 	// Store the return value in the result operand
 	returnValue := completedFrame.getReturnValue()
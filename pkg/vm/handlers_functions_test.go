@@ -0,0 +1,184 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// downFunction returns function down($n) { if ($n < 1) return "done";
+// return down($n - 1); }, its recursive call a tail call if tail is set
+func downFunction(tail bool) *CompiledFunction {
+	n, cond, next, result := Operand{Type: OpCV, Value: 0}, Operand{Type: OpTmpVar, Value: 0},
+		Operand{Type: OpTmpVar, Value: 1}, Operand{Type: OpTmpVar, Value: 2}
+	call := Instruction{Opcode: OpDoFcall, Result: result}
+	if tail {
+		call.ExtendedValue = CallTail
+	}
+	return &CompiledFunction{
+		Name:      "down",
+		NumParams: 1,
+		NumLocals: 4,
+		Variables: []string{"n"},
+		Constants: []interface{}{int64(1), "done", "down"},
+		Instructions: Instructions{
+			{Opcode: OpRecv, Op1: Operand{Type: OpConst, Value: 0}, Result: n},
+			{Opcode: OpIsSmaller, Op1: n, Op2: Operand{Type: OpConst, Value: 0}, Result: cond},
+			{Opcode: OpJmpZ, Op1: cond, Op2: Operand{Type: OpConst, Value: 4}},
+			{Opcode: OpReturn, Op1: Operand{Type: OpConst, Value: 1}},
+			{Opcode: OpSub, Op1: n, Op2: Operand{Type: OpConst, Value: 0}, Result: next},
+			{Opcode: OpInitFcallByName, Op1: Operand{Type: OpConst, Value: 2}},
+			{Opcode: OpSendVal, Op1: next},
+			call,
+			{Opcode: OpReturn, Op1: result},
+		},
+	}
+}
+
+// callDown returns the instructions of echo down(<constants[arg]>), with
+// the function name in constants[0]
+func callDown(arg uint32) Instructions {
+	return Instructions{
+		{Opcode: OpInitFcall, Op2: Operand{Type: OpConst, Value: 0}, ExtendedValue: 1},
+		{Opcode: OpSendVal, Op1: Operand{Type: OpConst, Value: arg}},
+		{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 0}},
+		{Opcode: OpEcho, Op1: Operand{Type: OpTmpVar, Value: 0}},
+	}
+}
+
+// setNestingLevel sets xdebug.max_nesting_level as ini_set() does
+func setNestingLevel(t *testing.T, vm *VM, level string) {
+	t.Helper()
+	if _, err := vm.Config().Set("xdebug.max_nesting_level", level, runtime.INI_USER); err != nil {
+		t.Fatalf("Setting xdebug.max_nesting_level: %v", err)
+	}
+}
+
+func TestDoFcall_SelfTailCall(t *testing.T) {
+	vm := New()
+	vm.RegisterFunction("down", downFunction(true))
+	vm.constants = []interface{}{"down", int64(1000)}
+	setNestingLevel(t, vm, "20")
+
+	// The recursion runs in one frame, far deeper than the nesting level
+	if err := vm.Execute(callDown(1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm.GetOutput() != "done" {
+		t.Errorf("Expected output 'done', got %q", vm.GetOutput())
+	}
+
+	// Without the tail call it reaches the nesting level
+	vm = New()
+	vm.RegisterFunction("down", downFunction(false))
+	vm.constants = []interface{}{"down", int64(1000)}
+	setNestingLevel(t, vm, "20")
+	err := vm.Execute(callDown(1))
+	if err == nil || !strings.Contains(err.Error(), "Maximum function nesting level of '20' reached") {
+		t.Errorf("Expected the nesting level to be reached, got %v", err)
+	}
+}
+
+func TestDoFcall_TailCallNeedsSameFunction(t *testing.T) {
+	vm := New()
+	vm.RegisterFunction("down", downFunction(false))
+	// A call marked as a tail call that calls another function runs
+	// normally: up($n) returns down($n)
+	up := downFunction(true)
+	up.Name = "up"
+	up.Instructions = Instructions{
+		{Opcode: OpRecv, Op1: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpCV, Value: 0}},
+		{Opcode: OpInitFcallByName, Op1: Operand{Type: OpConst, Value: 2}},
+		{Opcode: OpSendVal, Op1: Operand{Type: OpCV, Value: 0}},
+		{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 0}, ExtendedValue: CallTail},
+		{Opcode: OpReturn, Op1: Operand{Type: OpTmpVar, Value: 0}},
+	}
+	vm.RegisterFunction("up", up)
+	vm.constants = []interface{}{"up", int64(3)}
+
+	if err := vm.Execute(callDown(1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm.GetOutput() != "done" {
+		t.Errorf("Expected output 'done', got %q", vm.GetOutput())
+	}
+}
+
+func TestDoFcall_MaxNestingLevelIsCatchable(t *testing.T) {
+	vm := New()
+	vm.RegisterFunction("down", downFunction(false))
+	vm.constants = []interface{}{"down", int64(100), "Error", "getMessage"}
+	setNestingLevel(t, vm, "50")
+
+	// try { echo down(100); } catch (Error $e) { echo $e->getMessage(); }
+	instrs := append(callDown(1),
+		Instruction{Opcode: OpJmp, Op1: Operand{Value: 8}},
+		Instruction{Opcode: OpCatch, Op1: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpCV, Value: 0}, ExtendedValue: CatchLast},
+		Instruction{Opcode: OpInitMethodCall, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 3}},
+		Instruction{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 1}},
+		Instruction{Opcode: OpEcho, Op1: Operand{Type: OpTmpVar, Value: 1}},
+	)
+	fn := &CompiledFunction{
+		Name:         "main",
+		Instructions: instrs,
+		NumLocals:    10,
+		TryCatch:     []types.TryCatchElement{{TryOp: 0, CatchOp: 5}},
+	}
+
+	if err := runMain(vm, fn); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "Maximum function nesting level of '50' reached, aborting!"; vm.GetOutput() != want {
+		t.Errorf("Expected output %q, got %q", want, vm.GetOutput())
+	}
+	if vm.frameIndex != 0 {
+		t.Errorf("Expected the calls to be unwound to the main frame, got depth %d", vm.frameIndex)
+	}
+}
+
+func TestDoFcall_NestingLevelBeyondInitialFrames(t *testing.T) {
+	vm := New()
+	vm.RegisterFunction("down", downFunction(false))
+	vm.constants = []interface{}{"down", int64(2000)}
+	setNestingLevel(t, vm, "5000")
+
+	if err := vm.Execute(callDown(1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm.GetOutput() != "done" {
+		t.Errorf("Expected output 'done', got %q", vm.GetOutput())
+	}
+}
+
+func TestDoFcall_InvalidNestingLevel(t *testing.T) {
+	vm := New()
+	for _, level := range []string{"0", "-5", "deep"} {
+		_, err := vm.Config().Set("xdebug.max_nesting_level", level, runtime.INI_USER)
+		if err == nil {
+			t.Errorf("xdebug.max_nesting_level=%s should be refused", level)
+		}
+	}
+	if vm.maxStackDepth != 1000 {
+		t.Errorf("Refused values should keep the nesting level at 1000, got %d", vm.maxStackDepth)
+	}
+}
+
+func TestDoFcall_ReusesArguments(t *testing.T) {
+	vm := New()
+	vm.RegisterFunction("down", downFunction(false))
+	vm.constants = []interface{}{"down", int64(40)}
+	program := callDown(1)
+
+	// The calls reuse the frames and argument lists of finished calls
+	allocs := testing.AllocsPerRun(10, func() {
+		vm.ClearOutput()
+		if err := vm.Execute(program); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	if allocs >= 40 {
+		t.Errorf("Expected fewer allocations than calls, got %.0f for 40 calls", allocs)
+	}
+}
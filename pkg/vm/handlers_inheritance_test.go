@@ -21,7 +21,7 @@ func TestParentMethodCall_Basic(t *testing.T) {
 		// return "Hello from Parent"
 		Instructions: []interface{}{
 			Instruction{
-				Opcode: OpFetchConstant,
+				Opcode: OpQMAssign,
 				Op1:    Operand{Type: OpConst, Value: 0}, // "Hello from Parent"
 				Result: Operand{Type: OpTmpVar, Value: 0},
 			},
@@ -102,7 +102,7 @@ func TestParentMethodCall_OverriddenMethod(t *testing.T) {
 		// return 100
 		Instructions: []interface{}{
 			Instruction{
-				Opcode: OpFetchConstant,
+				Opcode: OpQMAssign,
 				Op1:    Operand{Type: OpConst, Value: 0}, // 100
 				Result: Operand{Type: OpTmpVar, Value: 0},
 			},
@@ -125,7 +125,7 @@ func TestParentMethodCall_OverriddenMethod(t *testing.T) {
 		// return 200
 		Instructions: []interface{}{
 			Instruction{
-				Opcode: OpFetchConstant,
+				Opcode: OpQMAssign,
 				Op1:    Operand{Type: OpConst, Value: 1}, // 200
 				Result: Operand{Type: OpTmpVar, Value: 0},
 			},
@@ -233,7 +233,8 @@ func TestAbstractClass_CannotInstantiate(t *testing.T) {
 		t.Fatal("Expected error when instantiating abstract class")
 	}
 
-	if err.Error() != "Cannot instantiate abstract class 'AbstractClass'" {
+	thrown, ok := err.(*ThrowableError)
+	if !ok || throwableProperty(thrown.Object, "message").ToString() != "Cannot instantiate abstract class AbstractClass" {
 		t.Errorf("Unexpected error: %v", err)
 	}
 }
@@ -357,3 +358,169 @@ func TestFinalMethod_CannotOverride(t *testing.T) {
 		t.Errorf("Expected error '%s', got '%s'", expectedMsg, err.Error())
 	}
 }
+
+func TestDeclareClass_AbstractMethods(t *testing.T) {
+	vm := New()
+
+	parent := types.NewClassEntry("Shape")
+	parent.IsAbstract = true
+	parent.Methods["area"] = &types.MethodDef{Name: "area", Visibility: types.VisibilityPublic, IsAbstract: true, DeclaringClass: "Shape"}
+	if err := vm.DeclareClass(parent); err != nil {
+		t.Fatalf("Expected an abstract class to be declared, got %v", err)
+	}
+
+	child := types.NewClassEntry("Circle")
+	child.InheritFrom(parent)
+	err := vm.DeclareClass(child)
+	want := "Class Circle contains 1 abstract method and must therefore be declared abstract or implement the remaining methods (Shape::area)"
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q, got %v", want, err)
+	}
+	if _, exists := vm.lookupClass("Circle"); exists {
+		t.Error("Expected an incomplete class not to be declared")
+	}
+
+	// Traits cannot be instantiated either
+	trait := types.NewClassEntry("Greets")
+	trait.IsTrait = true
+	vm.RegisterClass(trait)
+	vm.constants = []interface{}{"Greets"}
+	frame := NewFrame(mainScript(nil))
+	err = vm.dispatch(frame, Instruction{Opcode: OpNew, Op1: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 0}})
+	thrown, ok := err.(*ThrowableError)
+	if !ok || throwableProperty(thrown.Object, "message").ToString() != "Cannot instantiate trait Greets" {
+		t.Errorf("Expected an Error instantiating a trait, got %v", err)
+	}
+}
+
+func TestOpFetchClassConstant_Interface(t *testing.T) {
+	vm := New()
+
+	iface := types.NewInterfaceEntry("HasVersion")
+	iface.Constants["VERSION"] = &types.ClassConstant{Name: "VERSION", Value: types.NewString("1.0"), Visibility: types.VisibilityPublic}
+	ifaceClass := types.NewClassEntry("HasVersion")
+	ifaceClass.IsInterface = true
+	ifaceClass.Constants = iface.Constants
+	vm.RegisterClass(ifaceClass)
+
+	class := types.NewClassEntry("App")
+	class.Interfaces = append(class.Interfaces, iface)
+	vm.RegisterClass(class)
+
+	frame := NewFrame(mainScript(nil))
+	vm.constants = []interface{}{"HasVersion", "App", "VERSION"}
+	for _, name := range []uint32{0, 1} {
+		instr := Instruction{Opcode: OpFetchClassConstant, Op1: Operand{Type: OpConst, Value: name}, Op2: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpTmpVar, Value: 10}}
+		if err := vm.dispatch(frame, instr); err != nil {
+			t.Fatalf("%s::VERSION: unexpected error: %v", vm.constants[name], err)
+		}
+		if got := frame.getLocal(10).ToString(); got != "1.0" {
+			t.Errorf("%s::VERSION: expected 1.0, got %s", vm.constants[name], got)
+		}
+	}
+}
+
+// ============================================================================
+// Late Static Binding Tests
+// ============================================================================
+
+// callStatic runs Class::method() from the given frame and returns its result
+func callStatic(t *testing.T, vm *VM, frame *Frame, class, method uint32) *types.Value {
+	t.Helper()
+	for _, instr := range []Instruction{
+		{Opcode: OpInitStaticMethodCall, Op1: Operand{Type: OpConst, Value: class}, Op2: Operand{Type: OpConst, Value: method}},
+		{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 0}},
+	} {
+		if err := vm.dispatch(frame, instr); err != nil {
+			t.Fatalf("%s: unexpected error: %v", instr.Opcode, err)
+		}
+	}
+	return frame.getLocal(0)
+}
+
+func TestLateStaticBinding(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"static", "create", "Model", "User", "Admin", "parent", "build", "self", "buildSelf", "buildModel", "TABLE", "get_called_class", "table", "who"}
+
+	// Model::create() { return new static; }
+	// Model::table() { return static::TABLE; }
+	// Model::who() { return get_called_class(); }
+	model := types.NewClassEntry("Model")
+	model.Constants["TABLE"] = &types.ClassConstant{Name: "TABLE", Value: types.NewString("models"), Visibility: types.VisibilityPublic}
+	for name, body := range map[string][]interface{}{
+		"create": {
+			Instruction{Opcode: OpNew, Op1: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 0}},
+			Instruction{Opcode: OpReturn, Op1: Operand{Type: OpTmpVar, Value: 0}},
+		},
+		"table": {
+			Instruction{Opcode: OpFetchClassConstant, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 10}, Result: Operand{Type: OpTmpVar, Value: 0}},
+			Instruction{Opcode: OpReturn, Op1: Operand{Type: OpTmpVar, Value: 0}},
+		},
+		"who": {
+			Instruction{Opcode: OpInitFcallByName, Op1: Operand{Type: OpConst, Value: 11}},
+			Instruction{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 0}},
+			Instruction{Opcode: OpReturn, Op1: Operand{Type: OpTmpVar, Value: 0}},
+		},
+	} {
+		model.Methods[name] = &types.MethodDef{Name: name, Visibility: types.VisibilityPublic, IsStatic: true, DeclaringClass: "Model", Instructions: body, NumLocals: 10}
+	}
+	vm.RegisterClass(model)
+
+	// User::build() { return parent::create(); }
+	// User::buildSelf() { return self::create(); }
+	// User::buildModel() { return Model::create(); }
+	user := types.NewClassEntry("User")
+	user.Constants["TABLE"] = &types.ClassConstant{Name: "TABLE", Value: types.NewString("users"), Visibility: types.VisibilityPublic}
+	for name, class := range map[string]uint32{"build": 5, "buildSelf": 7, "buildModel": 2} {
+		user.Methods[name] = &types.MethodDef{Name: name, Visibility: types.VisibilityPublic, IsStatic: true, DeclaringClass: "User", NumLocals: 10,
+			Instructions: []interface{}{
+				Instruction{Opcode: OpInitStaticMethodCall, Op1: Operand{Type: OpConst, Value: class}, Op2: Operand{Type: OpConst, Value: 1}},
+				Instruction{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 0}},
+				Instruction{Opcode: OpReturn, Op1: Operand{Type: OpTmpVar, Value: 0}},
+			}}
+	}
+	if err := user.InheritFrom(model); err != nil {
+		t.Fatalf("InheritFrom failed: %v", err)
+	}
+	vm.RegisterClass(user)
+
+	admin := types.NewClassEntry("Admin")
+	if err := admin.InheritFrom(user); err != nil {
+		t.Fatalf("InheritFrom failed: %v", err)
+	}
+	vm.RegisterClass(admin)
+
+	frame := NewFrame(mainScript(nil))
+	vm.pushFrame(frame)
+
+	tests := []struct {
+		class, method uint32
+		want          string
+	}{
+		{3, 1, "User"},  // User::create()
+		{4, 6, "Admin"}, // Admin::build() forwards through parent::
+		{4, 8, "Admin"}, // Admin::buildSelf() forwards through self::
+		{4, 9, "Model"}, // Admin::buildModel() names the class
+		{2, 1, "Model"}, // Model::create()
+	}
+	for _, tt := range tests {
+		result := callStatic(t, vm, frame, tt.class, tt.method)
+		if !result.IsObject() || result.ToObject().ClassName != tt.want {
+			t.Errorf("%s::%s(): expected a %s, got %v", vm.constants[tt.class], vm.constants[tt.method], tt.want, result)
+		}
+	}
+
+	if got := callStatic(t, vm, frame, 4, 12).ToString(); got != "users" {
+		t.Errorf("Admin::table(): expected static::TABLE to be users, got %s", got)
+	}
+	if got := callStatic(t, vm, frame, 4, 13).ToString(); got != "Admin" {
+		t.Errorf("Admin::who(): expected get_called_class() to be Admin, got %s", got)
+	}
+
+	// static outside a class
+	err := vm.dispatch(frame, Instruction{Opcode: OpNew, Op1: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 0}})
+	thrown, ok := err.(*ThrowableError)
+	if !ok || throwableProperty(thrown.Object, "message").ToString() != `Cannot use "static" when no class scope is active` {
+		t.Errorf("Expected an Error for new static outside a class, got %v", err)
+	}
+}
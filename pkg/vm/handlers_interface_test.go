@@ -303,6 +303,66 @@ func TestInstanceof_ExtendedInterface(t *testing.T) {
 	}
 }
 
+func TestInstanceof_Operands(t *testing.T) {
+	vm := New()
+	iface := types.NewInterfaceEntry("Shape")
+	base := types.NewClassEntry("Base")
+	base.Interfaces = []*types.InterfaceEntry{iface}
+	child := types.NewClassEntry("Child")
+	child.ParentClass = base
+	vm.classes["Base"] = base
+	vm.classes["Child"] = child
+	obj := types.NewObject(types.NewObjectFromClass(child))
+
+	tests := []struct {
+		name     string
+		value    *types.Value
+		class    *types.Value
+		expected bool
+	}{
+		{"parent class", obj, types.NewString("Base"), true},
+		{"interface of parent", obj, types.NewString("shape"), true},
+		{"fully qualified", obj, types.NewString("\\Child"), true},
+		{"undeclared class", obj, types.NewString("Missing"), false},
+		{"object operand", obj, types.NewObject(types.NewObjectFromClass(base)), true},
+		{"subclass object operand", types.NewObject(types.NewObjectFromClass(base)), obj, false},
+		{"string value", types.NewString("Child"), types.NewString("Child"), false},
+		{"null value", types.NewNull(), types.NewString("Base"), false},
+		{"self", obj, types.NewString("self"), true},
+	}
+
+	for _, tt := range tests {
+		// $result = $value instanceof $class;
+		frame := NewFrame(&CompiledFunction{Name: "main", NumLocals: 10})
+		frame.currentClass = child
+		frame.setLocal(0, tt.value)
+		frame.setLocal(1, tt.class)
+		instr := Instruction{
+			Opcode: OpInstanceof,
+			Op1:    Operand{Type: OpCV, Value: 0},
+			Op2:    Operand{Type: OpCV, Value: 1},
+			Result: Operand{Type: OpCV, Value: 2},
+		}
+		if err := vm.opInstanceof(frame, instr); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got := frame.getLocal(2).ToBool(); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+
+	frame := NewFrame(&CompiledFunction{Name: "main", NumLocals: 10})
+	frame.setLocal(0, obj)
+	frame.setLocal(1, types.NewInt(1))
+	err := vm.opInstanceof(frame, Instruction{Opcode: OpInstanceof, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpCV, Value: 1}, Result: Operand{Type: OpCV, Value: 2}})
+	expectThrown(t, err, "Error", "Class name must be a valid object or a string")
+
+	frame.setLocal(1, types.NewString("parent"))
+	err = vm.opInstanceof(frame, Instruction{Opcode: OpInstanceof, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpCV, Value: 1}, Result: Operand{Type: OpCV, Value: 2}})
+	expectThrown(t, err, "Error", "Cannot use \"parent\" when no class scope is active")
+}
+
 // ============================================================================
 // Interface Validation Tests
 // ============================================================================
@@ -13,7 +13,10 @@ func (vm *VM) opEcho(frame *Frame, instr Instruction) error {
 	}
 
 	// Convert to string and write to output
-	output := value.ToString()
+	output, err := vm.stringValue(value)
+	if err != nil {
+		return err
+	}
 	vm.writeOutput([]byte(output))
 
 	return nil
@@ -18,100 +18,62 @@ func (vm *VM) opBoolNot(frame *Frame, instr Instruction) error {
 	return vm.setOperandValue(frame, instr.Result, result)
 }
 
-// opBWNot handles bitwise NOT (~)
-func (vm *VM) opBWNot(frame *Frame, instr Instruction) error {
+// opBool converts op1 to a boolean, for the operands of && and ||
+func (vm *VM) opBool(frame *Frame, instr Instruction) error {
 	operand, err := vm.getOperandValue(frame, instr.Op1)
 	if err != nil {
 		return err
 	}
-
-	// Bitwise NOT on integer
-	result := types.NewInt(^operand.ToInt())
-
-	return vm.setOperandValue(frame, instr.Result, result)
+	return vm.setOperandValue(frame, instr.Result, types.NewBool(operand.ToBool()))
 }
 
-// opBWAnd handles bitwise AND (&)
-func (vm *VM) opBWAnd(frame *Frame, instr Instruction) error {
+// opBoolXor handles logical XOR (xor)
+func (vm *VM) opBoolXor(frame *Frame, instr Instruction) error {
 	left, err := vm.getOperandValue(frame, instr.Op1)
 	if err != nil {
 		return err
 	}
-
 	right, err := vm.getOperandValue(frame, instr.Op2)
 	if err != nil {
 		return err
 	}
-
-	result := types.NewInt(left.ToInt() & right.ToInt())
-
-	return vm.setOperandValue(frame, instr.Result, result)
+	return vm.setOperandValue(frame, instr.Result, types.NewBool(left.ToBool() != right.ToBool()))
 }
 
-// opBWOr handles bitwise OR (|)
-func (vm *VM) opBWOr(frame *Frame, instr Instruction) error {
-	left, err := vm.getOperandValue(frame, instr.Op1)
-	if err != nil {
-		return err
-	}
-
-	right, err := vm.getOperandValue(frame, instr.Op2)
+// opBWNot handles bitwise NOT (~)
+func (vm *VM) opBWNot(frame *Frame, instr Instruction) error {
+	operand, err := vm.getOperandValue(frame, instr.Op1)
 	if err != nil {
 		return err
 	}
 
-	result := types.NewInt(left.ToInt() | right.ToInt())
+	// Bitwise NOT on integer
+	result := types.NewInt(^operand.ToInt())
 
 	return vm.setOperandValue(frame, instr.Result, result)
 }
 
-// opBWXor handles bitwise XOR (^)
-func (vm *VM) opBWXor(frame *Frame, instr Instruction) error {
-	left, err := vm.getOperandValue(frame, instr.Op1)
-	if err != nil {
-		return err
-	}
-
-	right, err := vm.getOperandValue(frame, instr.Op2)
-	if err != nil {
-		return err
-	}
+// opBWAnd handles bitwise AND (&)
+func (vm *VM) opBWAnd(frame *Frame, instr Instruction) error {
+	return vm.opBinary(frame, instr)
+}
 
-	result := types.NewInt(left.ToInt() ^ right.ToInt())
+// opBWOr handles bitwise OR (|)
+func (vm *VM) opBWOr(frame *Frame, instr Instruction) error {
+	return vm.opBinary(frame, instr)
+}
 
-	return vm.setOperandValue(frame, instr.Result, result)
+// opBWXor handles bitwise XOR (^)
+func (vm *VM) opBWXor(frame *Frame, instr Instruction) error {
+	return vm.opBinary(frame, instr)
 }
 
 // opShiftLeft handles left shift (<<)
 func (vm *VM) opShiftLeft(frame *Frame, instr Instruction) error {
-	left, err := vm.getOperandValue(frame, instr.Op1)
-	if err != nil {
-		return err
-	}
-
-	right, err := vm.getOperandValue(frame, instr.Op2)
-	if err != nil {
-		return err
-	}
-
-	result := types.NewInt(left.ToInt() << uint(right.ToInt()))
-
-	return vm.setOperandValue(frame, instr.Result, result)
+	return vm.opBinary(frame, instr)
 }
 
 // opShiftRight handles right shift (>>)
 func (vm *VM) opShiftRight(frame *Frame, instr Instruction) error {
-	left, err := vm.getOperandValue(frame, instr.Op1)
-	if err != nil {
-		return err
-	}
-
-	right, err := vm.getOperandValue(frame, instr.Op2)
-	if err != nil {
-		return err
-	}
-
-	result := types.NewInt(left.ToInt() >> uint(right.ToInt()))
-
-	return vm.setOperandValue(frame, instr.Result, result)
+	return vm.opBinary(frame, instr)
 }
@@ -94,7 +94,8 @@ func TestOpNew_AbstractClass(t *testing.T) {
 		t.Fatal("Expected error when instantiating abstract class")
 	}
 
-	if err.Error() != "Cannot instantiate abstract class 'AbstractClass'" {
+	thrown, ok := err.(*ThrowableError)
+	if !ok || throwableProperty(thrown.Object, "message").ToString() != "Cannot instantiate abstract class AbstractClass" {
 		t.Errorf("Unexpected error message: %v", err)
 	}
 }
@@ -131,7 +132,8 @@ func TestOpNew_InterfaceClass(t *testing.T) {
 		t.Fatal("Expected error when instantiating interface")
 	}
 
-	if err.Error() != "Cannot instantiate interface 'TestInterface'" {
+	thrown, ok := err.(*ThrowableError)
+	if !ok || throwableProperty(thrown.Object, "message").ToString() != "Cannot instantiate interface TestInterface" {
 		t.Errorf("Unexpected error message: %v", err)
 	}
 }
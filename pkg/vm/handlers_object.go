@@ -2,6 +2,7 @@ package vm
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/krizos/php-go/pkg/types"
 )
@@ -35,9 +36,7 @@ func (vm *VM) opFetchObjR(frame *Frame, instr Instruction) error {
 	propNameStr := propName.ToString()
 
 	// Get current class context for visibility checking
-	// For now, assume public access (nil context)
-	// TODO: Track current class context in frame
-	var accessContext *types.ClassEntry = nil
+	accessContext := frame.currentClass
 
 	// Get property value
 	value, exists := obj.GetProperty(propNameStr, accessContext)
@@ -59,7 +58,8 @@ func (vm *VM) opFetchObjR(frame *Frame, instr Instruction) error {
 	return vm.setOperandValue(frame, instr.Result, value)
 }
 
-// opFetchObjW handles fetching object property for write: $obj->prop = ...
+// opFetchObjW fetches an object property to write elements into:
+// $obj->prop[$key] = ...
 // OpFetchObjW - Fetch object property for write
 func (vm *VM) opFetchObjW(frame *Frame, instr Instruction) error {
 	// Get the object
@@ -88,25 +88,28 @@ func (vm *VM) opFetchObjW(frame *Frame, instr Instruction) error {
 	propNameStr := propName.ToString()
 
 	// Get current class context for visibility checking
-	var accessContext *types.ClassEntry = nil
+	accessContext := frame.currentClass
 
 	// Check if property exists
 	value, exists := obj.GetProperty(propNameStr, accessContext)
-	if !exists {
-		// Property doesn't exist - create it or call __set magic method
-		if obj.ClassEntry != nil {
-			if magicSet, hasMagic := obj.ClassEntry.MagicMethods["__set"]; hasMagic {
-				// TODO: Call __set($name, $value) magic method
-				_ = magicSet
+	if exists && obj.IsReadonlyInitialized(propNameStr) && value.Type() != types.TypeObject {
+		// Objects held by readonly properties can still be modified
+		return vm.ThrowError("Error", "Cannot modify readonly property %s::$%s", obj.ClassName, propNameStr)
+	}
+	if !exists || value.Deref().Type() == types.TypeNull {
+		// The elements written into a missing or null property create it
+		// as an array
+		array := types.NewArray(types.NewEmptyArray())
+		if !exists || !value.Assign(array) {
+			if err := vm.assignProperty(frame, obj, propNameStr, array); err != nil {
+				return err
 			}
 		}
-		// Create new property with null value
-		value = types.NewNull()
-		obj.SetProperty(propNameStr, value, accessContext)
+		value = array
 	}
 
-	// Return the property value (for write fetch, we return reference to the property)
-	return vm.setOperandValue(frame, instr.Result, value)
+	// The array is written into through its handle, which the property holds
+	return vm.setOperandValue(frame, instr.Result, value.Deref())
 }
 
 // opFetchObjRW handles fetching object property for read-write: $obj->prop += 1
@@ -137,7 +140,7 @@ func (vm *VM) opFetchObjIs(frame *Frame, instr Instruction) error {
 	}
 	propNameStr := propName.ToString()
 
-	var accessContext *types.ClassEntry = nil
+	accessContext := frame.currentClass
 
 	// Check for __isset magic method first
 	if obj.ClassEntry != nil {
@@ -204,24 +207,28 @@ func (vm *VM) opAssignObj(frame *Frame, instr Instruction) error {
 		return err
 	}
 
-	// Get current class context for visibility checking
-	var accessContext *types.ClassEntry = nil
-
-	// Check for __set magic method
-	if obj.ClassEntry != nil {
-		if magicSet, hasMagic := obj.ClassEntry.MagicMethods["__set"]; hasMagic {
-			// If property doesn't exist or is not accessible, use __set
-			if _, exists := obj.GetProperty(propNameStr, accessContext); !exists {
-				// TODO: Call __set($name, $value) magic method
-				_ = magicSet
-				// For now, fall through to direct assignment
-			}
+	// If the property doesn't exist or is not accessible, use __set
+	if _, exists := obj.GetProperty(propNameStr, frame.currentClass); !exists {
+		if _, called, err := vm.callMagic(obj, "__set", propName, value); called || err != nil {
+			return err
 		}
 	}
 
 	// Set the property
-	obj.SetProperty(propNameStr, value, accessContext)
+	return vm.assignProperty(frame, obj, propNameStr, value)
+}
 
+// assignProperty sets a property from the scope of the executing code,
+// throwing an Error when visibility or readonly rules forbid the write and
+// a TypeError when the value does not match the property's type
+func (vm *VM) assignProperty(frame *Frame, obj *types.Object, name string, value *types.Value) error {
+	value, err := vm.checkPropertyType(frame, obj, name, value)
+	if err != nil {
+		return err
+	}
+	if err := obj.AssignProperty(name, value, frame.currentClass); err != nil {
+		return vm.ThrowError("Error", "%s", err.Error())
+	}
 	return nil
 }
 
@@ -247,7 +254,7 @@ func (vm *VM) opAssignObjOp(frame *Frame, instr Instruction) error {
 	}
 	propNameStr := propName.ToString()
 
-	var accessContext *types.ClassEntry = nil
+	accessContext := frame.currentClass
 
 	// Get the current value
 	currentVal, exists := obj.GetProperty(propNameStr, accessContext)
@@ -261,13 +268,13 @@ func (vm *VM) opAssignObjOp(frame *Frame, instr Instruction) error {
 		return err
 	}
 
-	// Perform the operation (this would come from extended opcode info)
-	// For simplicity, assume += operation
-	// TODO: Get actual operation type from instruction
-	newVal := types.NewInt(currentVal.ToInt() + operand.ToInt())
+	// ExtendedValue holds the opcode of the binary operator
+	newVal, err := vm.binaryOp(Opcode(instr.ExtendedValue), currentVal, operand)
+	if err != nil {
+		return err
+	}
 
-	obj.SetProperty(propNameStr, newVal, accessContext)
-	return nil
+	return vm.assignProperty(frame, obj, propNameStr, newVal)
 }
 
 // opAssignObjRef handles object property assignment by reference: $obj->prop =& $var
@@ -298,12 +305,8 @@ func (vm *VM) opAssignObjRef(frame *Frame, instr Instruction) error {
 		return err
 	}
 
-	var accessContext *types.ClassEntry = nil
-
 	// Set the property (references handled by value system)
-	obj.SetProperty(propNameStr, value, accessContext)
-
-	return nil
+	return vm.assignProperty(frame, obj, propNameStr, value)
 }
 
 // ============================================================================
@@ -312,12 +315,15 @@ func (vm *VM) opAssignObjRef(frame *Frame, instr Instruction) error {
 
 // opUnsetObj handles unsetting object property: unset($obj->prop)
 // OpUnsetObj - Unset object property
+// Properties not accessible from the current scope are unset through
+// __unset() when the class defines it.
 func (vm *VM) opUnsetObj(frame *Frame, instr Instruction) error {
 	// Get the object
 	objVal, err := vm.getOperandValue(frame, instr.Op1)
 	if err != nil {
 		return err
 	}
+	objVal = objVal.Deref()
 
 	if objVal.Type() != types.TypeObject {
 		// Unset on non-object is a no-op
@@ -333,18 +339,20 @@ func (vm *VM) opUnsetObj(frame *Frame, instr Instruction) error {
 	}
 	propNameStr := propName.ToString()
 
-	// Check for __unset magic method
-	if obj.ClassEntry != nil {
-		if magicUnset, hasMagic := obj.ClassEntry.MagicMethods["__unset"]; hasMagic {
-			// TODO: Call __unset($name) magic method
-			_ = magicUnset
-			// For now, fall through to direct unset
+	if _, accessible := obj.GetProperty(propNameStr, frame.currentClass); accessible {
+		if err := obj.UnsetProperty(propNameStr, frame.currentClass); err != nil {
+			return vm.ThrowError("Error", "%s", err.Error())
 		}
+		return nil
 	}
 
-	// Remove the property
-	delete(obj.Properties, propNameStr)
+	if _, called, err := vm.callMagic(obj, "__unset", propName); called || err != nil {
+		return err
+	}
 
+	if prop, exists := obj.Properties[propNameStr]; exists {
+		return vm.ThrowError("Error", "Cannot access %s property %s::$%s", prop.Visibility, obj.ClassName, propNameStr)
+	}
 	return nil
 }
 
@@ -352,18 +360,21 @@ func (vm *VM) opUnsetObj(frame *Frame, instr Instruction) error {
 // Object Property Isset/Empty Operations
 // ============================================================================
 
-// opIssetIsemptyPropObj handles isset/empty check on object property
-// OpIssetIsemptyPropObj - Check isset/empty on object property
+// opIssetIsemptyPropObj handles isset($obj->prop) and empty($obj->prop)
+// Op1: object, Op2: property name, ExtendedValue: IssetIsEmpty for empty()
+// Properties not accessible from the current scope are checked with
+// __isset() and, for empty(), read with __get().
 func (vm *VM) opIssetIsemptyPropObj(frame *Frame, instr Instruction) error {
 	// Get the object
 	objVal, err := vm.getOperandValue(frame, instr.Op1)
 	if err != nil {
 		return err
 	}
+	objVal = objVal.Deref()
 
 	if objVal.Type() != types.TypeObject {
 		// Non-object is considered not set
-		return vm.setOperandValue(frame, instr.Result, types.NewBool(false))
+		return vm.setOperandValue(frame, instr.Result, issetOrEmpty(nil, false, instr))
 	}
 
 	obj := objVal.ToObject()
@@ -373,29 +384,25 @@ func (vm *VM) opIssetIsemptyPropObj(frame *Frame, instr Instruction) error {
 	if err != nil {
 		return err
 	}
-	propNameStr := propName.ToString()
-
-	var accessContext *types.ClassEntry = nil
 
-	// Check for __isset magic method
-	if obj.ClassEntry != nil {
-		if magicIsset, hasMagic := obj.ClassEntry.MagicMethods["__isset"]; hasMagic {
-			// TODO: Call __isset($name) magic method and return result
-			_ = magicIsset
-			// For now, fall through to direct check
+	value, exists := obj.GetProperty(propName.ToString(), frame.currentClass)
+	if !exists {
+		result, called, err := vm.callMagic(obj, "__isset", propName)
+		if err != nil {
+			return err
+		}
+		if called && result.ToBool() {
+			exists = true
+			value = types.NewBool(true)
+			if instr.ExtendedValue&IssetIsEmpty != 0 {
+				if value, _, err = vm.callMagic(obj, "__get", propName); err != nil {
+					return err
+				}
+			}
 		}
 	}
 
-	value, exists := obj.GetProperty(propNameStr, accessContext)
-
-	var result bool
-	// For isset: check if exists and not null
-	// For empty: check if exists and is "empty" (falsy)
-	// TODO: Determine from instruction if this is isset or empty check
-	// For now, implement isset semantics
-	result = exists && !value.IsNull()
-
-	return vm.setOperandValue(frame, instr.Result, types.NewBool(result))
+	return vm.setOperandValue(frame, instr.Result, issetOrEmpty(value, exists, instr))
 }
 
 // ============================================================================
@@ -405,86 +412,39 @@ func (vm *VM) opIssetIsemptyPropObj(frame *Frame, instr Instruction) error {
 // opPreIncObj handles pre-increment object property: result = ++$obj->prop
 // OpPreIncObj - Pre-increment object property
 func (vm *VM) opPreIncObj(frame *Frame, instr Instruction) error {
-	// Get the object
-	objVal, err := vm.getOperandValue(frame, instr.Op1)
-	if err != nil {
-		return err
-	}
-
-	if objVal.Type() != types.TypeObject {
-		return fmt.Errorf("PRE_INC_OBJ: not an object")
-	}
-
-	obj := objVal.ToObject()
-
-	// Get the property name
-	propName, err := vm.getOperandValue(frame, instr.Op2)
-	if err != nil {
-		return err
-	}
-	propNameStr := propName.ToString()
-
-	var accessContext *types.ClassEntry = nil
-
-	// Get current value
-	currentVal, exists := obj.GetProperty(propNameStr, accessContext)
-	if !exists {
-		currentVal = types.NewInt(0)
-	}
-
-	// Increment
-	newVal := types.NewInt(currentVal.ToInt() + 1)
-
-	// Set back
-	obj.SetProperty(propNameStr, newVal, accessContext)
-
-	// Return new value
-	return vm.setOperandValue(frame, instr.Result, newVal)
+	return vm.incDecProperty(frame, instr, true, false)
 }
 
 // opPreDecObj handles pre-decrement object property: result = --$obj->prop
 // OpPreDecObj - Pre-decrement object property
 func (vm *VM) opPreDecObj(frame *Frame, instr Instruction) error {
-	objVal, err := vm.getOperandValue(frame, instr.Op1)
-	if err != nil {
-		return err
-	}
-
-	if objVal.Type() != types.TypeObject {
-		return fmt.Errorf("PRE_DEC_OBJ: not an object")
-	}
-
-	obj := objVal.ToObject()
-
-	propName, err := vm.getOperandValue(frame, instr.Op2)
-	if err != nil {
-		return err
-	}
-	propNameStr := propName.ToString()
-
-	var accessContext *types.ClassEntry = nil
-
-	currentVal, exists := obj.GetProperty(propNameStr, accessContext)
-	if !exists {
-		currentVal = types.NewInt(0)
-	}
-
-	newVal := types.NewInt(currentVal.ToInt() - 1)
-	obj.SetProperty(propNameStr, newVal, accessContext)
-
-	return vm.setOperandValue(frame, instr.Result, newVal)
+	return vm.incDecProperty(frame, instr, false, false)
 }
 
 // opPostIncObj handles post-increment object property: result = $obj->prop++
 // OpPostIncObj - Post-increment object property
 func (vm *VM) opPostIncObj(frame *Frame, instr Instruction) error {
+	return vm.incDecProperty(frame, instr, true, true)
+}
+
+// opPostDecObj handles post-decrement object property: result = $obj->prop--
+// OpPostDecObj - Post-decrement object property
+func (vm *VM) opPostDecObj(frame *Frame, instr Instruction) error {
+	return vm.incDecProperty(frame, instr, false, true)
+}
+
+// incDecProperty increments or decrements the property Op2 of the object
+// Op1, storing the old (postfix) or new (prefix) value in Result
+func (vm *VM) incDecProperty(frame *Frame, instr Instruction, increment, postfix bool) error {
+	name := instr.Opcode.String()
+
 	objVal, err := vm.getOperandValue(frame, instr.Op1)
 	if err != nil {
 		return err
 	}
 
 	if objVal.Type() != types.TypeObject {
-		return fmt.Errorf("POST_INC_OBJ: not an object")
+		return fmt.Errorf("%s: not an object", name)
 	}
 
 	obj := objVal.ToObject()
@@ -495,58 +455,24 @@ func (vm *VM) opPostIncObj(frame *Frame, instr Instruction) error {
 	}
 	propNameStr := propName.ToString()
 
-	var accessContext *types.ClassEntry = nil
-
-	currentVal, exists := obj.GetProperty(propNameStr, accessContext)
+	// Undefined properties count as null
+	currentVal, exists := obj.GetProperty(propNameStr, frame.currentClass)
 	if !exists {
-		currentVal = types.NewInt(0)
+		currentVal = types.NewNull()
 	}
 
-	// Return old value
-	oldVal := currentVal.Copy()
-
-	// Increment and set back
-	newVal := types.NewInt(currentVal.ToInt() + 1)
-	obj.SetProperty(propNameStr, newVal, accessContext)
-
-	return vm.setOperandValue(frame, instr.Result, oldVal)
-}
-
-// opPostDecObj handles post-decrement object property: result = $obj->prop--
-// OpPostDecObj - Post-decrement object property
-func (vm *VM) opPostDecObj(frame *Frame, instr Instruction) error {
-	objVal, err := vm.getOperandValue(frame, instr.Op1)
+	newVal, err := vm.incDec(currentVal, increment)
 	if err != nil {
 		return err
 	}
-
-	if objVal.Type() != types.TypeObject {
-		return fmt.Errorf("POST_DEC_OBJ: not an object")
-	}
-
-	obj := objVal.ToObject()
-
-	propName, err := vm.getOperandValue(frame, instr.Op2)
-	if err != nil {
+	if err := vm.assignProperty(frame, obj, propNameStr, newVal); err != nil {
 		return err
 	}
-	propNameStr := propName.ToString()
-
-	var accessContext *types.ClassEntry = nil
 
-	currentVal, exists := obj.GetProperty(propNameStr, accessContext)
-	if !exists {
-		currentVal = types.NewInt(0)
+	if postfix {
+		return vm.setOperandValue(frame, instr.Result, currentVal.Deref())
 	}
-
-	// Return old value
-	oldVal := currentVal.Copy()
-
-	// Decrement and set back
-	newVal := types.NewInt(currentVal.ToInt() - 1)
-	obj.SetProperty(propNameStr, newVal, accessContext)
-
-	return vm.setOperandValue(frame, instr.Result, oldVal)
+	return vm.setOperandValue(frame, instr.Result, newVal)
 }
 
 // ============================================================================
@@ -555,6 +481,11 @@ func (vm *VM) opPostDecObj(frame *Frame, instr Instruction) error {
 
 // opNew handles object instantiation: result = new Class()
 // OpNew - Create new object instance
+// Op1: class name, Op2: jump target past the constructor call (unused when
+// the constructor is called separately), Result: the object
+// With a jump target, NEW initializes the call of the constructor, whose
+// arguments and DO_FCALL follow; a class without a constructor jumps past
+// them, leaving the arguments unevaluated as PHP does.
 func (vm *VM) opNew(frame *Frame, instr Instruction) error {
 	// Get the class name
 	className, err := vm.getOperandValue(frame, instr.Op1)
@@ -563,28 +494,87 @@ func (vm *VM) opNew(frame *Frame, instr Instruction) error {
 	}
 	classNameStr := className.ToString()
 
-	// Look up the class in the VM's class registry
-	classEntry, exists := vm.classes[classNameStr]
-	if !exists {
+	// Look up the class in the VM's class registry; new self, new parent
+	// and new static are resolved in the current scope, new $obj creates
+	// an object of the class of $obj
+	classEntry, err := vm.classOperand(frame, className)
+	if err != nil {
+		return err
+	}
+	if classEntry == nil {
 		// Class not found - in PHP this is a fatal error
 		return fmt.Errorf("Class '%s' not found", classNameStr)
 	}
 
-	// Check if class is abstract or interface
-	if classEntry.IsAbstract {
-		return fmt.Errorf("Cannot instantiate abstract class '%s'", classNameStr)
+	obj, err := vm.newInstance(classEntry)
+	if err != nil {
+		return err
 	}
-	if classEntry.IsInterface {
-		return fmt.Errorf("Cannot instantiate interface '%s'", classNameStr)
+
+	if instr.Op2.Type != OpUnused {
+		if classEntry.Constructor == nil {
+			frame.ip = int(instr.Op2.Value)
+		} else {
+			frame.suspendPendingCall()
+			frame.pendingMethod = classEntry.Constructor
+			frame.pendingObject = obj
+		}
+	}
+
+	// Store the object in the result operand
+	return vm.setOperandValue(frame, instr.Result, types.NewObject(obj))
+}
+
+// newInstance creates an object of a class without calling its
+// constructor
+func (vm *VM) newInstance(classEntry *types.ClassEntry) (*types.Object, error) {
+	if classEntry.IsEnum {
+		return nil, vm.ThrowError("Error", "Cannot instantiate enum %s", classEntry.Name)
+	}
+
+	// Abstract classes, interfaces and traits cannot be instantiated
+	if !classEntry.IsInstantiable() {
+		kind := "abstract class"
+		if classEntry.IsInterface {
+			kind = "interface"
+		} else if classEntry.IsTrait {
+			kind = "trait"
+		}
+		return nil, vm.ThrowError("Error", "Cannot instantiate %s %s", kind, classEntry.Name)
 	}
 
-	// Create new object instance
 	obj := types.NewObjectFromClass(classEntry)
-	objVal := types.NewObject(obj)
 
-	// Store the object in the result operand
-	// The constructor will be called separately via OpInitMethodCall + OpDoFcall
-	return vm.setOperandValue(frame, instr.Result, objVal)
+	// Exceptions capture where they were created
+	if vm.isThrowable(classEntry) {
+		vm.initThrowable(obj)
+	}
+	vm.trackObject(obj)
+	return obj, nil
+}
+
+// instantiate creates an object of a class and calls its constructor with
+// the given arguments, like new Class(...$args) does
+func (vm *VM) instantiate(classEntry *types.ClassEntry, params *CallParams) (*types.Object, error) {
+	obj, err := vm.newInstance(classEntry)
+	if err != nil {
+		return nil, err
+	}
+	if classEntry.Constructor == nil {
+		return obj, nil
+	}
+
+	// Native constructors declaring their parameters accept named arguments
+	target := vm.methodTarget(classEntry, obj, classEntry.Constructor)
+	args, rest, err := vm.bindArguments(target.Name, target.parameters(), params)
+	if err != nil {
+		return nil, err
+	}
+	target.NamedRest = rest
+	if _, err := vm.invokeTarget(target, args); err != nil {
+		return nil, err
+	}
+	return obj, nil
 }
 
 // opInitMethodCall handles initialization of instance method call: $obj->method()
@@ -610,6 +600,9 @@ func (vm *VM) opInitMethodCall(frame *Frame, instr Instruction) error {
 	if err != nil {
 		return err
 	}
+	if !methodName.Deref().IsString() {
+		return vm.ThrowError("Error", "Method name must be a string")
+	}
 	methodNameStr := methodName.ToString()
 
 	// Check for class entry
@@ -637,8 +630,7 @@ func (vm *VM) opInitMethodCall(frame *Frame, instr Instruction) error {
 	// For now, we'll assume all methods are accessible
 
 	// Store method information for OpDoFcall
-	// We'll use frame locals to pass this information
-	// This is a simplified approach - a real implementation would use a call stack
+	frame.suspendPendingCall()
 	frame.pendingMethod = method
 	frame.pendingObject = obj
 
@@ -658,33 +650,13 @@ func (vm *VM) opInitStaticMethodCall(frame *Frame, instr Instruction) error {
 	}
 	classNameStr := className.ToString()
 
-	// Special handling for 'self', 'parent', 'static'
-	// TODO: Implement late static binding for 'static'
-	switch classNameStr {
-	case "self":
-		// Use current class
-		if frame.currentClass == nil {
-			return fmt.Errorf("INIT_STATIC_METHOD_CALL: 'self' used outside class context")
-		}
-		classNameStr = frame.currentClass.Name
-	case "parent":
-		// Use parent class
-		if frame.currentClass == nil || frame.currentClass.ParentClass == nil {
-			return fmt.Errorf("INIT_STATIC_METHOD_CALL: 'parent' used without parent class")
-		}
-		classNameStr = frame.currentClass.ParentClass.Name
-	case "static":
-		// Late static binding - use the called class
-		// For now, use current class (will implement LSB in later task)
-		if frame.currentClass == nil {
-			return fmt.Errorf("INIT_STATIC_METHOD_CALL: 'static' used outside class context")
-		}
-		classNameStr = frame.currentClass.Name
+	// self::, parent:: and static:: are resolved in the calling scope,
+	// $obj::method() calls a method of the class of $obj
+	classEntry, err := vm.classOperand(frame, className)
+	if err != nil {
+		return err
 	}
-
-	// Look up the class
-	classEntry, exists := vm.classes[classNameStr]
-	if !exists {
+	if classEntry == nil {
 		return fmt.Errorf("INIT_STATIC_METHOD_CALL: class '%s' not found", classNameStr)
 	}
 
@@ -715,8 +687,22 @@ func (vm *VM) opInitStaticMethodCall(frame *Frame, instr Instruction) error {
 	}
 
 	// Store method information for OpDoFcall
+	frame.suspendPendingCall()
 	frame.pendingMethod = method
 	frame.pendingObject = nil // No object for static calls
+	frame.pendingClass = classEntry
+
+	// Calls through self:: and parent:: (and static::) forward the called
+	// class; naming a class makes it the called class
+	frame.pendingCalledClass = classEntry
+	if isSpecialClassName(classNameStr) && frame.calledClass != nil {
+		frame.pendingCalledClass = frame.calledClass
+	}
+
+	// parent::method() and self::method() from an instance method keep $this
+	if !method.IsStatic && frame.thisObject != nil && vm.isInstanceOf(frame.thisObject.ClassEntry, classEntry.Name) {
+		frame.pendingObject = frame.thisObject
+	}
 
 	return nil
 }
@@ -735,14 +721,39 @@ func (vm *VM) opClone(frame *Frame, instr Instruction) error {
 	}
 
 	obj := objVal.ToObject()
+	if obj.ClassEntry != nil && obj.ClassEntry.IsEnum {
+		return vm.ThrowError("Error", "Trying to clone an uncloneable object of class %s", obj.ClassName)
+	}
+	newObj := cloneObject(obj)
+	vm.trackObject(newObj)
+
+	newObjVal := types.NewObject(newObj)
+
+	// Check for __clone magic method
+	if obj.ClassEntry != nil {
+		if magicClone, hasMagic := obj.ClassEntry.MagicMethods["__clone"]; hasMagic {
+			// TODO: Call __clone() on the new object
+			// The __clone method is called on the copy, not the original
+			_ = magicClone
+		}
+	}
+
+	return vm.setOperandValue(frame, instr.Result, newObjVal)
+}
 
+// cloneObject returns a shallow copy of an object with a new identity, as
+// the clone operator creates it
+func cloneObject(obj *types.Object) *types.Object {
 	// Create a shallow copy of the object
 	newObj := &types.Object{
 		ClassName:   obj.ClassName,
 		ClassEntry:  obj.ClassEntry,
 		Properties:  make(map[string]*types.Property),
 		ObjectID:    types.NextObjectID(),
+		Internal:    obj.Internal,
 		IsDestroyed: false,
+
+		PropertyOrder: obj.PropertyOrder[:len(obj.PropertyOrder):len(obj.PropertyOrder)],
 	}
 
 	// Copy properties
@@ -757,57 +768,58 @@ func (vm *VM) opClone(frame *Frame, instr Instruction) error {
 			Default:    prop.Default,
 			IsReadOnly: prop.IsReadOnly,
 			Hooks:      prop.Hooks,
-		}
-		newObj.Properties[name] = newProp
-	}
 
-	newObjVal := types.NewObject(newObj)
-
-	// Check for __clone magic method
-	if obj.ClassEntry != nil {
-		if magicClone, hasMagic := obj.ClassEntry.MagicMethods["__clone"]; hasMagic {
-			// TODO: Call __clone() on the new object
-			// The __clone method is called on the copy, not the original
-			_ = magicClone
+			DeclaringClass: prop.DeclaringClass,
 		}
+		newObj.Properties[name] = newProp
+		types.AddRef(newProp.Value)
 	}
 
-	return vm.setOperandValue(frame, instr.Result, newObjVal)
+	return newObj
 }
 
-// opInstanceof handles instanceof check: result = $obj instanceof Class
-// OpInstanceof - Check if object is instance of class
+// opInstanceof handles the instanceof operator: result = $value instanceof Class
+// Op1: value, Op2: class name, or an object whose class is used
+// Result: bool; values other than objects are never instances
 func (vm *VM) opInstanceof(frame *Frame, instr Instruction) error {
-	// Get the object/value
 	val, err := vm.getOperandValue(frame, instr.Op1)
 	if err != nil {
 		return err
 	}
-
-	// Get the class name
-	className, err := vm.getOperandValue(frame, instr.Op2)
+	target, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+	className, err := vm.instanceofClassName(frame, target.Deref())
 	if err != nil {
 		return err
 	}
-	classNameStr := className.ToString()
-
-	var result bool
 
-	// Only objects can be instanceof a class
-	if val.Type() != types.TypeObject {
-		result = false
-	} else {
-		obj := val.ToObject()
+	val = val.Deref()
+	result := val.IsObject() && vm.isInstanceOf(val.ToObject().ClassEntry, className)
+	return vm.setOperandValue(frame, instr.Result, types.NewBool(result))
+}
 
-		if obj.ClassEntry == nil {
-			result = false
-		} else {
-			// Check if object's class matches or is a subclass of the target class
-			result = vm.isInstanceOf(obj.ClassEntry, classNameStr)
-		}
+// instanceofClassName resolves the right-hand side of instanceof. As in
+// PHP, a class that is not declared is not loaded: no value is an
+// instance of it.
+func (vm *VM) instanceofClassName(frame *Frame, target *types.Value) (string, error) {
+	if target.IsObject() {
+		return target.ToObject().ClassName, nil
+	}
+	if !target.IsString() {
+		return "", vm.ThrowError("Error", "Class name must be a valid object or a string")
 	}
 
-	return vm.setOperandValue(frame, instr.Result, types.NewBool(result))
+	name := strings.TrimPrefix(target.ToString(), "\\")
+	if !isSpecialClassName(name) {
+		return name, nil
+	}
+	class, err := vm.classReference(frame, name)
+	if err != nil {
+		return "", err
+	}
+	return class.Name, nil
 }
 
 // isInstanceOf checks if a class is an instance of a target class (including inheritance and interfaces)
@@ -815,16 +827,17 @@ func (vm *VM) isInstanceOf(class *types.ClassEntry, targetClassName string) bool
 	if class == nil {
 		return false
 	}
+	targetClassName = strings.TrimPrefix(targetClassName, "\\")
 
-	// Check direct match
-	if class.Name == targetClassName {
+	// Check direct match (class names are case-insensitive)
+	if strings.EqualFold(class.Name, targetClassName) {
 		return true
 	}
 
 	// Check parent classes
 	current := class.ParentClass
 	for current != nil {
-		if current.Name == targetClassName {
+		if strings.EqualFold(current.Name, targetClassName) {
 			return true
 		}
 		current = current.ParentClass
@@ -864,13 +877,216 @@ func (vm *VM) opGetClass(frame *Frame, instr Instruction) error {
 	return vm.setOperandValue(frame, instr.Result, result)
 }
 
+// opGetCalledClass handles get_called_class()
+// Result: the name of the called class
+func (vm *VM) opGetCalledClass(frame *Frame, instr Instruction) error {
+	name, err := vm.calledClassName(frame)
+	if err != nil {
+		return err
+	}
+	return vm.setOperandValue(frame, instr.Result, name)
+}
+
+// opFetchClass looks up a class by name, autoloading it if it is not declared yet
+// Op1: class name (or an object, whose class is used)
+// Result: the declared class name
+func (vm *VM) opFetchClass(frame *Frame, instr Instruction) error {
+	classRef, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+
+	if classRef.Type() == types.TypeObject && classRef.ToObject().ClassEntry != nil {
+		return vm.setOperandValue(frame, instr.Result, types.NewString(classRef.ToObject().ClassEntry.Name))
+	}
+
+	name := classRef.ToString()
+	class, exists, err := vm.loadClass(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return vm.ThrowError("Error", "Class \"%s\" not found", strings.TrimPrefix(name, "\\"))
+	}
+
+	if instr.Result.Type != OpUnused {
+		return vm.setOperandValue(frame, instr.Result, types.NewString(class.Name))
+	}
+	return nil
+}
+
+// opFetchClassName resolves self::class, parent::class, static::class and $obj::class
+// Op1: "self", "parent", "static" or an object
+// Result: fully qualified class name
+func (vm *VM) opFetchClassName(frame *Frame, instr Instruction) error {
+	classRef, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+
+	var class *types.ClassEntry
+	if classRef.Type() == types.TypeObject {
+		class = classRef.ToObject().ClassEntry
+		if class == nil {
+			return vm.setOperandValue(frame, instr.Result, types.NewString(classRef.ToObject().ClassName))
+		}
+	} else {
+		ref := strings.ToLower(classRef.ToString())
+		switch ref {
+		case "self":
+			class = frame.currentClass
+		case "parent":
+			if frame.currentClass != nil {
+				class = frame.currentClass.ParentClass
+				if class == nil {
+					return fmt.Errorf("Cannot use \"parent\" when current class scope has no parent")
+				}
+			}
+		case "static":
+			class = frame.calledClass
+			if class == nil {
+				class = frame.currentClass
+			}
+		default:
+			return vm.ThrowError("TypeError", "Cannot use \"::class\" on value of type %s", classRef.TypeString())
+		}
+		if class == nil {
+			return fmt.Errorf("Cannot use \"%s\" when no class scope is active", ref)
+		}
+	}
+
+	return vm.setOperandValue(frame, instr.Result, types.NewString(class.Name))
+}
+
+// opFetchClassConstant fetches a class constant or enum case: Class::CONST
+// Op1: class name ("self", "parent" and "static" included), Op2: constant name
+// Result: the constant value
+func (vm *VM) opFetchClassConstant(frame *Frame, instr Instruction) error {
+	className, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	constName, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+
+	// $obj::CONST uses the class of the object
+	var class *types.ClassEntry
+	if className.Type() == types.TypeObject {
+		class = className.ToObject().ClassEntry
+	} else if class, err = vm.staticClass(frame, className.ToString()); err != nil {
+		return err
+	}
+	if class == nil {
+		return vm.ThrowError("Error", "Class \"%s\" not found", strings.TrimPrefix(className.ToString(), "\\"))
+	}
+
+	value, exists := class.GetStaticConstant(constName.ToString(), false, nil)
+	if !exists {
+		return vm.ThrowError("Error", "Undefined constant %s::%s", class.Name, constName.ToString())
+	}
+	return vm.setOperandValue(frame, instr.Result, value)
+}
+
+// ============================================================================
+// Static Properties
+// ============================================================================
+
+// StaticPropInit is set in the ExtendedValue of the ASSIGN_STATIC_PROP a
+// class initializer emits, which assigns a property of any visibility
+const StaticPropInit uint32 = 1
+
+// staticProperty resolves the class and name of a static property access
+// (Op1: class name or object, Op2: property name) and checks that the
+// property is declared and visible from the executing scope
+func (vm *VM) staticProperty(frame *Frame, instr Instruction) (*types.ClassEntry, string, error) {
+	classRef, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return nil, "", err
+	}
+	propName, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return nil, "", err
+	}
+	name := propName.ToString()
+
+	class, err := vm.classOperand(frame, classRef)
+	if err != nil {
+		return nil, "", err
+	}
+	if class == nil {
+		return nil, "", vm.ThrowError("Error", "Class \"%s\" not found", strings.TrimPrefix(classRef.ToString(), "\\"))
+	}
+
+	prop, ok := class.Properties[name]
+	if !ok || !prop.IsStatic {
+		return nil, "", vm.ThrowError("Error", "Access to undeclared static property %s::$%s", class.Name, name)
+	}
+	scope := frame.currentClass
+	if instr.ExtendedValue&StaticPropInit != 0 {
+		scope = class
+	}
+	if !vm.staticPropertyAccessible(prop, scope) {
+		return nil, "", vm.ThrowError("Error", "Cannot access %s property %s::$%s", prop.Visibility, class.Name, name)
+	}
+	return class, name, nil
+}
+
+// staticPropertyAccessible reports whether a static property is visible
+// from a class scope (nil outside classes)
+func (vm *VM) staticPropertyAccessible(prop *types.PropertyDef, scope *types.ClassEntry) bool {
+	switch prop.Visibility {
+	case types.VisibilityPublic:
+		return true
+	case types.VisibilityPrivate:
+		return scope != nil && strings.EqualFold(scope.Name, prop.DeclaringClass)
+	}
+	if scope == nil {
+		return false
+	}
+	declaring, ok := vm.lookupClass(prop.DeclaringClass)
+	return vm.isInstanceOf(scope, prop.DeclaringClass) || (ok && vm.isInstanceOf(declaring, scope.Name))
+}
+
+// opFetchStaticPropR reads a static property: Class::$prop, $class::$prop
+// Op1: class name or object, Op2: property name, Result: the value
+func (vm *VM) opFetchStaticPropR(frame *Frame, instr Instruction) error {
+	class, name, err := vm.staticProperty(frame, instr)
+	if err != nil {
+		return err
+	}
+	value, _ := class.GetStaticProperty(name)
+	return vm.setOperandValue(frame, instr.Result, value.Deref())
+}
+
+// opAssignStaticProp assigns a static property: Class::$prop = value
+// Op1: class name or object, Op2: property name, Result: the value
+func (vm *VM) opAssignStaticProp(frame *Frame, instr Instruction) error {
+	class, name, err := vm.staticProperty(frame, instr)
+	if err != nil {
+		return err
+	}
+	value, err := vm.getOperandValue(frame, instr.Result)
+	if err != nil {
+		return err
+	}
+	value = assignValue(value.Deref())
+	if slot, ok := class.GetStaticProperty(name); ok && slot.Assign(value) {
+		return nil
+	}
+	class.SetStaticProperty(name, value)
+	return nil
+}
+
 // opFetchThis handles fetching $this variable
 // OpFetchThis - Fetch $this variable
 func (vm *VM) opFetchThis(frame *Frame, instr Instruction) error {
 	// Get $this from frame context
 	if frame.thisObject == nil {
-		// $this is not available (static context or function context)
-		return fmt.Errorf("FETCH_THIS: $this not available in this context")
+		// $this is not available (static context, function or unbound
+		// closure)
+		return vm.ThrowError("Error", "Using $this when not in object context")
 	}
 
 	return vm.setOperandValue(frame, instr.Result, types.NewObject(frame.thisObject))
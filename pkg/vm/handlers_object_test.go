@@ -549,10 +549,11 @@ func TestOpAssignObjOp_Addition(t *testing.T) {
 	frame.setLocal(2, types.NewInt(5)) // Add 5
 
 	instr := Instruction{
-		Opcode: OpAssignObjOp,
-		Op1:    Operand{Type: OpTmpVar, Value: 0},
-		Op2:    Operand{Type: OpTmpVar, Value: 1},
-		Result: Operand{Type: OpTmpVar, Value: 2},
+		Opcode:        OpAssignObjOp,
+		Op1:           Operand{Type: OpTmpVar, Value: 0},
+		Op2:           Operand{Type: OpTmpVar, Value: 1},
+		Result:        Operand{Type: OpTmpVar, Value: 2},
+		ExtendedValue: uint32(OpAdd),
 	}
 
 	err := vm.opAssignObjOp(frame, instr)
@@ -566,3 +567,66 @@ func TestOpAssignObjOp_Addition(t *testing.T) {
 		t.Errorf("Expected property value 15, got %d", prop.Value.ToInt())
 	}
 }
+
+func TestOpAssignObj_Readonly(t *testing.T) {
+	vm := New()
+
+	class := types.NewClassEntry("Point")
+	class.Properties["x"] = &types.PropertyDef{Name: "x", Type: "int", IsReadOnly: true}
+	obj := types.NewObjectFromClass(class)
+
+	fn := &CompiledFunction{Instructions: Instructions{}, NumLocals: 10}
+	frame := NewFrame(fn)
+	frame.setLocal(0, types.NewObject(obj))
+	frame.setLocal(1, types.NewString("x"))
+	frame.setLocal(2, types.NewInt(1))
+
+	assign := Instruction{
+		Opcode: OpAssignObj,
+		Op1:    Operand{Type: OpTmpVar, Value: 0},
+		Op2:    Operand{Type: OpTmpVar, Value: 1},
+		Result: Operand{Type: OpTmpVar, Value: 2},
+	}
+	inc := Instruction{
+		Opcode: OpPreIncObj,
+		Op1:    Operand{Type: OpTmpVar, Value: 0},
+		Op2:    Operand{Type: OpTmpVar, Value: 1},
+		Result: Operand{Type: OpTmpVar, Value: 3},
+	}
+	unset := Instruction{
+		Opcode: OpUnsetObj,
+		Op1:    Operand{Type: OpTmpVar, Value: 0},
+		Op2:    Operand{Type: OpTmpVar, Value: 1},
+	}
+
+	tests := []struct {
+		scope *types.ClassEntry
+		instr Instruction
+		err   string
+	}{
+		{nil, assign, "Cannot initialize readonly property Point::$x from global scope"},
+		{class, assign, ""},
+		{class, assign, "Cannot modify readonly property Point::$x"},
+		{class, inc, "Cannot modify readonly property Point::$x"},
+		{nil, unset, "Cannot unset readonly property Point::$x"},
+	}
+
+	for i, tt := range tests {
+		frame.currentClass = tt.scope
+		err := vm.dispatch(frame, tt.instr)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		thrown, ok := err.(*ThrowableError)
+		if !ok || throwableProperty(thrown.Object, "message").ToString() != tt.err {
+			t.Errorf("%d: expected Error %q, got %v", i, tt.err, err)
+		}
+	}
+
+	if value, _ := obj.GetProperty("x", nil); value.ToInt() != 1 {
+		t.Errorf("Expected x to keep its initial value, got %v", value)
+	}
+}
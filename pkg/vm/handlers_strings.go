@@ -19,12 +19,68 @@ func (vm *VM) opConcat(frame *Frame, instr Instruction) error {
 	}
 
 	// Convert both to strings and concatenate
-	result := types.NewString(left.ToString() + right.ToString())
-
-	return vm.setOperandValue(frame, instr.Result, result)
+	l, err := vm.stringValue(left)
+	if err != nil {
+		return err
+	}
+	r, err := vm.stringValue(right)
+	if err != nil {
+		return err
+	}
+	return vm.setOperandValue(frame, instr.Result, types.NewString(l+r))
 }
 
-// opFastConcat handles optimized string concatenation (same as opConcat for now)
+// opFastConcat concatenates two operands the optimizer found to be strings,
+// falling back to opConcat for other values
 func (vm *VM) opFastConcat(frame *Frame, instr Instruction) error {
-	return vm.opConcat(frame, instr)
+	left, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	right, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+	if !left.IsString() || !right.IsString() {
+		return vm.opConcat(frame, instr)
+	}
+	return vm.setOperandValue(frame, instr.Result, types.NewString(left.ToString()+right.ToString()))
+}
+
+// opRopeInit starts the rope of an interpolated string
+// Op2: first part, Result: rope, ExtendedValue: number of parts
+func (vm *VM) opRopeInit(frame *Frame, instr Instruction) error {
+	part, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+	s, err := vm.stringValue(part)
+	if err != nil {
+		return err
+	}
+	return vm.setOperandValue(frame, instr.Result, types.NewString(s))
+}
+
+// opRopeAdd appends a part to a rope
+// Op1: rope, Op2: part, Result: rope, ExtendedValue: part index
+func (vm *VM) opRopeAdd(frame *Frame, instr Instruction) error {
+	rope, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	part, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+	s, err := vm.stringValue(part)
+	if err != nil {
+		return err
+	}
+	return vm.setOperandValue(frame, instr.Result, types.NewString(rope.ToString()+s))
+}
+
+// opRopeEnd appends the last part and stores the finished string
+// Op1: rope, Op2: last part (unused for a single-part rope), Result: string
+func (vm *VM) opRopeEnd(frame *Frame, instr Instruction) error {
+	return vm.opRopeAdd(frame, instr)
 }
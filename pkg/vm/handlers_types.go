@@ -0,0 +1,82 @@
+package vm
+
+import (
+	"github.com/krizos/php-go/pkg/stdlib/vartype"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Type Checks and Casts
+// ============================================================================
+
+// Type bits of the ExtendedValue of TYPE_CHECK, which tests whether the
+// type of op1 is one of those set
+const (
+	TypeCheckNull uint32 = 1 << iota
+	TypeCheckFalse
+	TypeCheckTrue
+	TypeCheckInt
+	TypeCheckFloat
+	TypeCheckString
+	TypeCheckArray
+	TypeCheckObject
+	TypeCheckResource
+
+	TypeCheckBool   = TypeCheckFalse | TypeCheckTrue
+	TypeCheckScalar = TypeCheckBool | TypeCheckInt | TypeCheckFloat | TypeCheckString
+)
+
+// typeCheckBit returns the TYPE_CHECK bit of a value's type; closed
+// resources have none
+func typeCheckBit(value *types.Value) uint32 {
+	switch value.Type() {
+	case types.TypeNull, types.TypeUndef:
+		return TypeCheckNull
+	case types.TypeBool:
+		if value.ToBool() {
+			return TypeCheckTrue
+		}
+		return TypeCheckFalse
+	case types.TypeInt:
+		return TypeCheckInt
+	case types.TypeFloat:
+		return TypeCheckFloat
+	case types.TypeString:
+		return TypeCheckString
+	case types.TypeArray:
+		return TypeCheckArray
+	case types.TypeObject:
+		return TypeCheckObject
+	case types.TypeResource:
+		if value.ToResource().IsValid() {
+			return TypeCheckResource
+		}
+	}
+	return 0
+}
+
+// opTypeCheck handles TYPE_CHECK: result = whether the type of op1 is
+// one of the ExtendedValue bits (is_int($x), is_scalar($x), ...)
+func (vm *VM) opTypeCheck(frame *Frame, instr Instruction) error {
+	value, err := vm.readOperand(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	matches := typeCheckBit(value.Deref())&instr.ExtendedValue != 0
+	return vm.setOperandValue(frame, instr.Result, types.NewBool(matches))
+}
+
+// opCast handles CAST: result = op1 converted to the vartype.CastKind in
+// the ExtendedValue, for the cast operators and intval($x), strval($x),
+// etc.
+func (vm *VM) opCast(frame *Frame, instr Instruction) error {
+	value, err := vm.readOperand(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	result, err := vm.converter().Cast(value, vartype.CastKind(instr.ExtendedValue))
+	if err != nil {
+		return vm.conversionError(err)
+	}
+	return vm.setOperandValue(frame, instr.Result, assignValue(result))
+}
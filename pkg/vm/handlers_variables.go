@@ -1,20 +1,22 @@
 package vm
 
-import "github.com/krizos/php-go/pkg/types"
+import (
+	"sort"
+
+	"github.com/krizos/php-go/pkg/types"
+)
 
 // ============================================================================
 // Variable Opcode Handlers
 // ============================================================================
 
-// opConst loads a constant value
-func (vm *VM) opConst(frame *Frame, instr Instruction) error {
-	// Op1 contains the constant index
-	value, err := vm.GetConstant(int(instr.Op1.Value))
+// opQMAssign copies a value into a temporary: result = op1
+func (vm *VM) opQMAssign(frame *Frame, instr Instruction) error {
+	value, err := vm.getOperandValue(frame, instr.Op1)
 	if err != nil {
 		return err
 	}
-
-	return vm.setOperandValue(frame, instr.Result, value)
+	return vm.setOperandValue(frame, instr.Result, assignValue(value.Deref()))
 }
 
 // opAssign handles variable assignment
@@ -25,50 +27,366 @@ func (vm *VM) opAssign(frame *Frame, instr Instruction) error {
 		return err
 	}
 
+	// $$name = value: Op1 holds the name, Result receives the value
+	if instr.ExtendedValue&FetchByName != 0 {
+		name, err := vm.operandName(frame, instr.Op1)
+		if err != nil {
+			return err
+		}
+		value = assignValue(value.Deref())
+		if err := vm.assignVariable(frame, name, value); err != nil {
+			return err
+		}
+		return vm.setOperandValue(frame, instr.Result, value)
+	}
+
 	// Assign to result/Op1
-	return vm.setOperandValue(frame, instr.Result, value)
+	return vm.setOperandValue(frame, instr.Result, assignValue(value))
+}
+
+// opAssignRef binds a variable to a reference: op1 =& op2
+// Op1: compiled variable (its name with FetchByName), Op2: reference (as
+// fetched by FETCH_LIST_W)
+func (vm *VM) opAssignRef(frame *Frame, instr Instruction) error {
+	ref, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+	if !ref.IsReference() {
+		ref = types.NewReference(assignValue(ref))
+	}
+	if instr.ExtendedValue&FetchByName != 0 {
+		name, err := vm.operandName(frame, instr.Op1)
+		if err != nil {
+			return err
+		}
+		return vm.bindVariable(frame, name, ref)
+	}
+	frame.setLocal(int(instr.Op1.Value), ref)
+	return nil
+}
+
+// assignValue returns the value to store for an assignment. Arrays have value
+// semantics, so they are copied (an O(1) copy-on-write copy) to keep the
+// target from aliasing the source.
+func assignValue(value *types.Value) *types.Value {
+	if value.Type() == types.TypeArray {
+		return value.Copy()
+	}
+	return value
+}
+
+// ============================================================================
+// Compound Assignment and Increment/Decrement
+// ============================================================================
+
+// opAssignOp handles compound assignment: result = op1 <op>= op2
+// Op1: variable, Op2: value, ExtendedValue: opcode of the binary operator
+// (OpAdd for +=, OpConcat for .=, ...)
+func (vm *VM) opAssignOp(frame *Frame, instr Instruction) error {
+	current, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+
+	value, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+
+	result, err := vm.binaryOp(Opcode(instr.ExtendedValue), current, value)
+	if err != nil {
+		return err
+	}
+
+	if err := vm.setOperandValue(frame, instr.Op1, result); err != nil {
+		return err
+	}
+	return vm.setOperandValue(frame, instr.Result, result)
+}
+
+// opPreInc handles pre-increment: result = ++op1
+func (vm *VM) opPreInc(frame *Frame, instr Instruction) error {
+	return vm.incDecVariable(frame, instr, true, false)
+}
+
+// opPreDec handles pre-decrement: result = --op1
+func (vm *VM) opPreDec(frame *Frame, instr Instruction) error {
+	return vm.incDecVariable(frame, instr, false, false)
+}
+
+// opPostInc handles post-increment: result = op1++
+func (vm *VM) opPostInc(frame *Frame, instr Instruction) error {
+	return vm.incDecVariable(frame, instr, true, true)
+}
+
+// opPostDec handles post-decrement: result = op1--
+func (vm *VM) opPostDec(frame *Frame, instr Instruction) error {
+	return vm.incDecVariable(frame, instr, false, true)
+}
+
+// incDecVariable increments or decrements the variable in Op1, storing the
+// old (postfix) or new (prefix) value in Result
+func (vm *VM) incDecVariable(frame *Frame, instr Instruction, increment, postfix bool) error {
+	current, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+
+	updated, err := vm.incDec(current, increment)
+	if err != nil {
+		return err
+	}
+
+	if err := vm.setOperandValue(frame, instr.Op1, updated); err != nil {
+		return err
+	}
+	if postfix {
+		return vm.setOperandValue(frame, instr.Result, current.Deref())
+	}
+	return vm.setOperandValue(frame, instr.Result, updated)
+}
+
+// incDec returns value incremented or decremented, throwing a TypeError for
+// types that do not support it
+func (vm *VM) incDec(value *types.Value, increment bool) (*types.Value, error) {
+	if increment {
+		if result, ok := value.Increment(); ok {
+			return result, nil
+		}
+		return nil, vm.ThrowError("TypeError", "Cannot increment %s", value.TypeName())
+	}
+	if result, ok := value.Decrement(); ok {
+		return result, nil
+	}
+	return nil, vm.ThrowError("TypeError", "Cannot decrement %s", value.TypeName())
 }
 
 // opFetch handles variable fetch (read)
 func (vm *VM) opFetch(frame *Frame, instr Instruction) error {
+	if instr.ExtendedValue&FetchByName != 0 {
+		return vm.fetchByName(frame, instr, false)
+	}
+
 	// Get the variable value
 	value, err := vm.getOperandValue(frame, instr.Op1)
 	if err != nil {
 		return err
 	}
+	if instr.Op1.Type == OpCV && frame.isUndefinedLocal(int(instr.Op1.Value)) {
+		vm.warnUndefinedVariable(frame, int(instr.Op1.Value))
+		value = types.NewNull()
+	}
 
 	// Store in result
 	return vm.setOperandValue(frame, instr.Result, value)
 }
 
+// opFetchIs reads a variable for isset(), empty() or ??: an undefined
+// variable is null without a warning
+func (vm *VM) opFetchIs(frame *Frame, instr Instruction) error {
+	if instr.ExtendedValue&FetchByName != 0 {
+		return vm.fetchByName(frame, instr, true)
+	}
+
+	value, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	value = value.Deref()
+	if value.IsUndef() {
+		value = types.NewNull()
+	}
+	return vm.setOperandValue(frame, instr.Result, value)
+}
+
+// readOperand returns the value of an operand read as an argument of a
+// builtin the compiler inlined (is_int($x), intval($x), ...): an
+// undefined compiled variable warns and reads as null, as FETCH_R does
+func (vm *VM) readOperand(frame *Frame, op Operand) (*types.Value, error) {
+	if op.Type == OpCV && frame.isUndefinedLocal(int(op.Value)) {
+		vm.warnUndefinedVariable(frame, int(op.Value))
+		return types.NewNull(), nil
+	}
+	return vm.getOperandValue(frame, op)
+}
+
+// isUndefinedLocal reports whether a compiled variable was never assigned
+// or has been unset
+func (f *Frame) isUndefinedLocal(index int) bool {
+	return index >= len(f.locals) || f.locals[index].Deref().IsUndef()
+}
+
+// warnUndefinedVariable warns about reading an unset compiled variable,
+// if the frame knows the variable's name
+func (vm *VM) warnUndefinedVariable(frame *Frame, index int) {
+	if index < len(frame.fn.Variables) {
+		vm.warning("Undefined variable $%s", frame.fn.Variables[index])
+	}
+}
+
 // opUnset handles unsetting a variable
 func (vm *VM) opUnset(frame *Frame, instr Instruction) error {
-	// Set variable to null/undef
+	if instr.ExtendedValue&FetchByName != 0 {
+		name, err := vm.operandName(frame, instr.Op1)
+		if err != nil {
+			return err
+		}
+		vm.unsetVariable(frame, name)
+		return nil
+	}
+
+	// Set variable to null/undef, breaking any reference it holds
+	if instr.Op1.Type == OpCV || instr.Op1.Type == OpVar {
+		index := int(instr.Op1.Value)
+		vm.releaseReference(frame.getLocal(index))
+		if frame.globalScope && index < len(frame.fn.Variables) {
+			delete(vm.globals, frame.fn.Variables[index])
+		}
+		frame.setLocal(index, types.NewUndef())
+		return nil
+	}
 	return vm.setOperandValue(frame, instr.Op1, types.NewUndef())
 }
 
-// opIsset handles isset() check
-func (vm *VM) opIsset(frame *Frame, instr Instruction) error {
-	value, err := vm.getOperandValue(frame, instr.Op1)
+// opFree discards a temporary the compiler no longer needs, such as the
+// result of an expression statement. Temporaries share the locals with
+// the compiled variables (see CompiledFunction.tempSlot).
+// Op1: temporary
+func (vm *VM) opFree(frame *Frame, instr Instruction) error {
+	if instr.Op1.Type != OpTmpVar {
+		return nil
+	}
+	if index := frame.fn.tempSlot(instr.Op1.Value); index < len(frame.locals) {
+		frame.setLocal(index, nil)
+	}
+	return nil
+}
+
+// IssetIsEmpty is set in the ExtendedValue of the ISSET_ISEMPTY_* opcodes
+// compiled for empty(); without it they implement isset()
+const IssetIsEmpty uint32 = 1
+
+// issetOrEmpty returns the result of isset() on a variable, element or
+// property (it exists and is not null) or, for empty(), whether it is
+// missing or falsy
+func issetOrEmpty(value *types.Value, exists bool, instr Instruction) *types.Value {
+	value = value.Deref()
+	if instr.ExtendedValue&IssetIsEmpty != 0 {
+		return types.NewBool(!exists || !value.ToBool())
+	}
+	return types.NewBool(exists && !value.IsNull() && !value.IsUndef())
+}
+
+// opIssetIsemptyCV handles isset($var) and empty($var)
+// Op1: compiled variable, ExtendedValue: IssetIsEmpty for empty()
+func (vm *VM) opIssetIsemptyCV(frame *Frame, instr Instruction) error {
+	value := frame.getLocal(int(instr.Op1.Value))
+	return vm.setOperandValue(frame, instr.Result, issetOrEmpty(value, !value.Deref().IsUndef(), instr))
+}
+
+// ============================================================================
+// Global Variables
+// ============================================================================
+
+// opBindGlobal imports a global into the current scope: global $x
+// Op1: compiled variable, Op2: variable name (constant)
+func (vm *VM) opBindGlobal(frame *Frame, instr Instruction) error {
+	name, err := vm.getOperandValue(frame, instr.Op2)
 	if err != nil {
 		return err
 	}
+	frame.setLocal(int(instr.Op1.Value), vm.globalSlot(name.ToString()))
+	return nil
+}
+
+// opFetchGlobals fetches $GLOBALS
+// Result: array of the defined globals
+// The elements are the global slots themselves, so reads see the live
+// values and writes to existing keys update the globals.
+func (vm *VM) opFetchGlobals(frame *Frame, instr Instruction) error {
+	return vm.setOperandValue(frame, instr.Result, types.NewArray(vm.globalsArray()))
+}
 
-	// isset returns false for null and undef
-	result := !value.IsNull() && !value.IsUndef()
+// globalsArray builds the $GLOBALS array, sorted by name
+func (vm *VM) globalsArray() *types.Array {
+	names := make([]string, 0, len(vm.globals))
+	for name, slot := range vm.globals {
+		if !slot.Deref().IsUndef() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
 
-	return vm.setOperandValue(frame, instr.Result, types.NewBool(result))
+	arr := types.NewEmptyArray()
+	for _, name := range names {
+		arr.Set(types.NewString(name), vm.globals[name])
+	}
+	return arr
 }
 
-// opEmpty handles empty() check
-func (vm *VM) opEmpty(frame *Frame, instr Instruction) error {
-	value, err := vm.getOperandValue(frame, instr.Op1)
+// ============================================================================
+// Static Variables
+// ============================================================================
+
+// staticVarsFor returns the static variables of the executing function:
+// the closure instance's own, or those the VM keeps for the function
+func (vm *VM) staticVarsFor(frame *Frame) map[string]*types.Value {
+	if frame.staticVars == nil {
+		statics, ok := vm.staticVars[frame.fn]
+		if !ok {
+			statics = make(map[string]*types.Value)
+			vm.staticVars[frame.fn] = statics
+		}
+		frame.staticVars = statics
+	}
+	return frame.staticVars
+}
+
+// bindStatic makes a compiled variable a reference to a static slot
+func bindStatic(frame *Frame, target Operand, slot *types.Value) {
+	frame.setLocal(int(target.Value), slot)
+}
+
+// opBindStatic binds a static variable, initializing it on first use
+// Op1: variable name (constant), Op2: initial value (unused for null),
+// Result: compiled variable
+// If the variable was initialized meanwhile (by a recursive call made
+// from its initializer), the existing value is kept.
+func (vm *VM) opBindStatic(frame *Frame, instr Instruction) error {
+	name, err := vm.getOperandValue(frame, instr.Op1)
 	if err != nil {
 		return err
 	}
 
-	// empty() returns true for falsy values
-	result := value.IsFalse()
+	statics := vm.staticVarsFor(frame)
+	slot, ok := statics[name.ToString()]
+	if !ok {
+		initial, err := vm.getOperandValue(frame, instr.Op2)
+		if err != nil {
+			return err
+		}
+		slot = types.NewReference(assignValue(initial.Deref()))
+		statics[name.ToString()] = slot
+	}
 
-	return vm.setOperandValue(frame, instr.Result, types.NewBool(result))
+	bindStatic(frame, instr.Result, slot)
+	return nil
+}
+
+// opBindInitStaticOrJmp binds an already initialized static variable and
+// jumps past its initializer; otherwise execution continues into the
+// initializer, which ends with BIND_STATIC
+// Op1: variable name (constant), Op2: jump target, Result: compiled variable
+func (vm *VM) opBindInitStaticOrJmp(frame *Frame, instr Instruction) error {
+	name, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+
+	if slot, ok := vm.staticVarsFor(frame)[name.ToString()]; ok {
+		bindStatic(frame, instr.Result, slot)
+		frame.ip = int(instr.Op2.Value)
+	}
+	return nil
 }
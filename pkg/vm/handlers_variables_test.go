@@ -0,0 +1,497 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Static Variable Tests
+// ============================================================================
+
+// counterFunction returns a function computing
+//
+//	static $n = <initializer>; $n++; return $n;
+//
+// where the initializer instructions leave their result in TmpVar 5.
+// Constant 0 holds the variable name and constant 1 the integer 1.
+func counterFunction(name string, initializer ...Instruction) *CompiledFunction {
+	nameOp := Operand{Type: OpConst, Value: 0}
+	n := Operand{Type: OpCV, Value: 0}
+	skip := uint32(len(initializer) + 2)
+
+	instrs := Instructions{{Opcode: OpBindInitStaticOrJmp, Op1: nameOp, Op2: Operand{Type: OpConst, Value: skip}, Result: n}}
+	instrs = append(instrs, initializer...)
+	instrs = append(instrs,
+		Instruction{Opcode: OpBindStatic, Op1: nameOp, Op2: Operand{Type: OpTmpVar, Value: 5}, Result: n},
+		Instruction{Opcode: OpAdd, Op1: n, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 6}},
+		Instruction{Opcode: OpAssign, Op2: Operand{Type: OpTmpVar, Value: 6}, Result: n},
+		Instruction{Opcode: OpReturn, Op1: n},
+	)
+	return &CompiledFunction{Name: name, Instructions: instrs, NumLocals: 10}
+}
+
+// callCounter calls a counter function or closure and returns its result
+func callCounter(t *testing.T, vm *VM, callable *types.Value) int64 {
+	t.Helper()
+	result, err := vm.CallCallable(callable, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return result.ToInt()
+}
+
+func TestBindStatic_PersistsAcrossCalls(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"n", int64(1), int64(10)}
+	vm.RegisterFunction("counter", counterFunction("counter",
+		Instruction{Opcode: OpAssign, Op2: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpTmpVar, Value: 5}},
+	))
+
+	for want := int64(11); want <= 13; want++ {
+		if got := callCounter(t, vm, types.NewString("counter")); got != want {
+			t.Errorf("Expected %d, got %d", want, got)
+		}
+	}
+}
+
+func TestBindStatic_InitializesOnce(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"n", int64(1), "seed"}
+	seeded := 0
+	vm.RegisterBuiltin("seed", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		seeded++
+		return types.NewInt(100), nil
+	})
+	vm.RegisterFunction("counter", counterFunction("counter",
+		Instruction{Opcode: OpInitFcall, Op2: Operand{Type: OpConst, Value: 2}},
+		Instruction{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 5}},
+	))
+
+	callCounter(t, vm, types.NewString("counter"))
+	if got := callCounter(t, vm, types.NewString("counter")); got != 102 {
+		t.Errorf("Expected 102, got %d", got)
+	}
+	if seeded != 1 {
+		t.Errorf("Expected the initializer to run once, ran %d times", seeded)
+	}
+}
+
+func TestBindStatic_WithoutInitializerIsNull(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"n"}
+	fn := &CompiledFunction{
+		Name: "f",
+		Instructions: Instructions{
+			{Opcode: OpBindStatic, Op1: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpCV, Value: 0}},
+			{Opcode: OpReturn, Op1: Operand{Type: OpCV, Value: 0}},
+		},
+		NumLocals: 10,
+	}
+	vm.RegisterFunction("f", fn)
+
+	result, err := vm.CallCallable(types.NewString("f"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsNull() {
+		t.Errorf("Expected null, got %v", result)
+	}
+}
+
+func TestBindStatic_PerClosureInstance(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"n", int64(1), int64(0)}
+	fn := counterFunction("{closure}",
+		Instruction{Opcode: OpAssign, Op2: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpTmpVar, Value: 5}},
+	)
+	first := newClosureObject(&Closure{Function: fn, StaticVars: make(map[string]*types.Value)})
+	second := newClosureObject(&Closure{Function: fn, StaticVars: make(map[string]*types.Value)})
+
+	callCounter(t, vm, first)
+	callCounter(t, vm, first)
+	if got := callCounter(t, vm, second); got != 1 {
+		t.Errorf("Expected the second closure to have its own static, got %d", got)
+	}
+	if got := callCounter(t, vm, first); got != 3 {
+		t.Errorf("Expected 3, got %d", got)
+	}
+}
+
+func TestBindStatic_RecursiveInitializer(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"n", int64(1), "seed"}
+	depth := 0
+	vm.RegisterBuiltin("seed", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		depth++
+		defer func() { depth-- }()
+		if depth == 1 {
+			// Initializes the static through a nested call first
+			if _, err := vm.CallCallable(types.NewString("counter"), nil); err != nil {
+				return nil, err
+			}
+			return types.NewInt(99), nil
+		}
+		return types.NewInt(10), nil
+	})
+	vm.RegisterFunction("counter", counterFunction("counter",
+		Instruction{Opcode: OpInitFcall, Op2: Operand{Type: OpConst, Value: 2}},
+		Instruction{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 5}},
+	))
+
+	// The nested call stores 10 and increments it; the outer call keeps
+	// that value instead of its own initializer result
+	if got := callCounter(t, vm, types.NewString("counter")); got != 12 {
+		t.Errorf("Expected 12, got %d", got)
+	}
+}
+
+func TestUnset_BreaksStaticBinding(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"n", int64(5)}
+	fn := &CompiledFunction{
+		Name: "f",
+		Instructions: Instructions{
+			{Opcode: OpBindStatic, Op1: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpCV, Value: 0}},
+			{Opcode: OpUnsetVar, Op1: Operand{Type: OpCV, Value: 0}},
+			{Opcode: OpAssign, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpCV, Value: 0}},
+			{Opcode: OpReturn, Op1: Operand{Type: OpCV, Value: 0}},
+		},
+		NumLocals: 10,
+	}
+	vm.RegisterFunction("f", fn)
+
+	if _, err := vm.CallCallable(types.NewString("f"), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if static := vm.staticVars[fn]["n"]; !static.Deref().IsNull() {
+		t.Errorf("Expected the static to stay null after unset, got %v", static.Deref())
+	}
+}
+
+// ============================================================================
+// Global Variable Tests
+// ============================================================================
+
+func TestBindGlobal_SharesScriptVariables(t *testing.T) {
+	vm := New()
+	// function bump() { global $count; $count = $count + 1; }
+	vm.RegisterFunction("bump", &CompiledFunction{
+		Name: "bump",
+		Instructions: Instructions{
+			{Opcode: OpBindGlobal, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 0}},
+			{Opcode: OpAdd, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 5}},
+			{Opcode: OpAssign, Op2: Operand{Type: OpTmpVar, Value: 5}, Result: Operand{Type: OpCV, Value: 0}},
+			{Opcode: OpReturn},
+		},
+		NumLocals: 10,
+	})
+	vm.constants = []interface{}{"count", int64(1)}
+
+	// $count = 1; bump(); bump(); echo $count;
+	call := Instructions{
+		{Opcode: OpInitFcall, Op2: Operand{Type: OpConst, Value: 1}},
+		{Opcode: OpDoFcall},
+	}
+	instrs := Instructions{{Opcode: OpAssign, Op2: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpCV, Value: 0}}}
+	instrs = append(instrs, call...)
+	instrs = append(instrs, call...)
+	instrs = append(instrs, Instruction{Opcode: OpEcho, Op1: Operand{Type: OpCV, Value: 0}})
+
+	script := &Script{Instructions: instrs, Constants: []interface{}{int64(1), "bump"}, Variables: []string{"count"}}
+	err := vm.ExecuteScript(script)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm.GetOutput() != "3" {
+		t.Errorf("Expected 3, got %q", vm.GetOutput())
+	}
+	if count, ok := vm.GetGlobal("count"); !ok || count.ToInt() != 3 {
+		t.Errorf("Expected global $count to be 3, got %v", count)
+	}
+}
+
+func TestBindGlobal_CreatesUndefinedGlobal(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"created", "value"}
+	fn := &CompiledFunction{
+		Name: "f",
+		Instructions: Instructions{
+			{Opcode: OpBindGlobal, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 0}},
+			{Opcode: OpAssign, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpCV, Value: 0}},
+			{Opcode: OpReturn},
+		},
+		NumLocals: 10,
+	}
+	vm.RegisterFunction("f", fn)
+
+	if _, ok := vm.GetGlobal("created"); ok {
+		t.Fatal("Expected $created to be undefined before the call")
+	}
+	if _, err := vm.CallCallable(types.NewString("f"), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created, ok := vm.GetGlobal("created"); !ok || created.ToString() != "value" {
+		t.Errorf("Expected global $created to be 'value', got %v", created)
+	}
+}
+
+func TestFetchGlobals_ReflectsLiveBindings(t *testing.T) {
+	vm := New()
+	vm.SetGlobal("a", types.NewInt(1))
+	vm.constants = []interface{}{"a", int64(2)}
+
+	frame := NewFrame(&CompiledFunction{Name: "main", NumLocals: 10})
+	vm.pushFrame(frame)
+	instrs := []Instruction{
+		{Opcode: OpFetchGlobals, Result: Operand{Type: OpTmpVar, Value: 5}},
+		// $GLOBALS['a'] = 2 writes the global
+		{Opcode: OpAssignDim, Op1: Operand{Type: OpTmpVar, Value: 5}, Op2: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpConst, Value: 1}},
+		{Opcode: OpFetchDimR, Op1: Operand{Type: OpTmpVar, Value: 5}, Op2: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 6}},
+	}
+	for _, instr := range instrs {
+		if err := vm.dispatch(frame, instr); err != nil {
+			t.Fatalf("%s: unexpected error: %v", instr.Opcode, err)
+		}
+	}
+
+	if a, _ := vm.GetGlobal("a"); a.ToInt() != 2 {
+		t.Errorf("Expected the write through $GLOBALS to update $a, got %v", a)
+	}
+	if got := frame.getLocal(6); got.ToInt() != 2 {
+		t.Errorf("Expected $GLOBALS['a'] to read 2, got %v", got)
+	}
+}
+
+func TestUnset_RemovesGlobal(t *testing.T) {
+	vm := New()
+	instrs := Instructions{
+		{Opcode: OpAssign, Op2: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpCV, Value: 0}},
+		{Opcode: OpUnsetVar, Op1: Operand{Type: OpCV, Value: 0}},
+	}
+
+	script := &Script{Instructions: instrs, Constants: []interface{}{int64(1)}, Variables: []string{"x"}}
+	if err := vm.ExecuteScript(script); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := vm.GetGlobal("x"); ok {
+		t.Error("Expected unset($x) in the global scope to remove the global")
+	}
+}
+
+// ============================================================================
+// Compound Assignment and Increment/Decrement Tests
+// ============================================================================
+
+func TestAssignOp(t *testing.T) {
+	tests := []struct {
+		op      Opcode
+		initial *types.Value
+		value   *types.Value
+		want    *types.Value
+	}{
+		{OpAdd, types.NewInt(10), types.NewString("5"), types.NewInt(15)},
+		{OpSub, types.NewInt(10), types.NewInt(3), types.NewInt(7)},
+		{OpMul, types.NewString("2.5"), types.NewInt(2), types.NewFloat(5)},
+		{OpDiv, types.NewInt(9), types.NewInt(3), types.NewInt(3)},
+		{OpConcat, types.NewString("foo"), types.NewString("bar"), types.NewString("foobar")},
+		{OpSL, types.NewInt(1), types.NewInt(3), types.NewInt(8)},
+	}
+
+	for _, tt := range tests {
+		vm := New()
+		frame := NewFrame(&CompiledFunction{Name: "main", NumLocals: 10})
+		frame.setLocal(0, tt.initial)
+		frame.setLocal(1, tt.value)
+
+		instr := Instruction{
+			Opcode:        OpAssignOp,
+			Op1:           Operand{Type: OpCV, Value: 0},
+			Op2:           Operand{Type: OpCV, Value: 1},
+			Result:        Operand{Type: OpTmpVar, Value: 5},
+			ExtendedValue: uint32(tt.op),
+		}
+		if err := vm.dispatch(frame, instr); err != nil {
+			t.Errorf("%s=: unexpected error: %v", binaryOperators[tt.op], err)
+			continue
+		}
+		if got := frame.getLocal(0); !got.Identical(tt.want) {
+			t.Errorf("%s=: variable = %v, want %v", binaryOperators[tt.op], got, tt.want)
+		}
+		if got := frame.getLocal(5); !got.Identical(tt.want) {
+			t.Errorf("%s=: result = %v, want %v", binaryOperators[tt.op], got, tt.want)
+		}
+	}
+}
+
+func TestAssignOp_WritesThroughReference(t *testing.T) {
+	vm := New()
+	vm.SetGlobal("total", types.NewInt(1))
+	vm.constants = []interface{}{"total", int64(41)}
+
+	frame := NewFrame(&CompiledFunction{Name: "f", NumLocals: 10})
+	instrs := []Instruction{
+		{Opcode: OpBindGlobal, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 0}},
+		{Opcode: OpAssignOp, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 5}, ExtendedValue: uint32(OpAdd)},
+		{Opcode: OpPreInc, Op1: Operand{Type: OpCV, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 5}},
+	}
+	for _, instr := range instrs {
+		if err := vm.dispatch(frame, instr); err != nil {
+			t.Fatalf("%s: unexpected error: %v", instr.Opcode, err)
+		}
+	}
+
+	if total, _ := vm.GetGlobal("total"); total.ToInt() != 43 {
+		t.Errorf("Expected global $total to be 43, got %v", total)
+	}
+}
+
+func TestIncDec(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		initial  *types.Value
+		variable *types.Value
+		result   *types.Value
+	}{
+		{OpPreInc, types.NewInt(1), types.NewInt(2), types.NewInt(2)},
+		{OpPostInc, types.NewInt(1), types.NewInt(2), types.NewInt(1)},
+		{OpPreDec, types.NewInt(1), types.NewInt(0), types.NewInt(0)},
+		{OpPostDec, types.NewInt(1), types.NewInt(0), types.NewInt(1)},
+		{OpPostInc, types.NewString("z"), types.NewString("aa"), types.NewString("z")},
+		{OpPreInc, types.NewString("9.5"), types.NewFloat(10.5), types.NewFloat(10.5)},
+		{OpPreInc, types.NewUndef(), types.NewInt(1), types.NewInt(1)},
+		{OpPostDec, types.NewNull(), types.NewNull(), types.NewNull()},
+	}
+
+	for _, tt := range tests {
+		vm := New()
+		frame := NewFrame(&CompiledFunction{Name: "main", NumLocals: 10})
+		frame.setLocal(0, tt.initial)
+
+		instr := Instruction{Opcode: tt.op, Op1: Operand{Type: OpCV, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 5}}
+		if err := vm.dispatch(frame, instr); err != nil {
+			t.Errorf("%s %v: unexpected error: %v", tt.op, tt.initial, err)
+			continue
+		}
+		if got := frame.getLocal(0); !got.Identical(tt.variable) {
+			t.Errorf("%s %v: variable = %v, want %v", tt.op, tt.initial, got, tt.variable)
+		}
+		if got := frame.getLocal(5); !got.Identical(tt.result) {
+			t.Errorf("%s %v: result = %v, want %v", tt.op, tt.initial, got, tt.result)
+		}
+	}
+}
+
+func TestIncDec_ArrayThrows(t *testing.T) {
+	vm := New()
+	frame := NewFrame(&CompiledFunction{Name: "main", NumLocals: 10})
+	frame.setLocal(0, types.NewArray(types.NewEmptyArray()))
+
+	err := vm.dispatch(frame, Instruction{Opcode: OpPreInc, Op1: Operand{Type: OpCV, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 5}})
+	thrown, ok := err.(*ThrowableError)
+	if !ok || thrown.Object.ClassName != "TypeError" {
+		t.Fatalf("Expected TypeError, got %v", err)
+	}
+	if msg := throwableProperty(thrown.Object, "message").ToString(); msg != "Cannot increment array" {
+		t.Errorf("Expected 'Cannot increment array', got %q", msg)
+	}
+}
+
+func TestAssignDimOp(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"count", int64(2)}
+	frame := NewFrame(&CompiledFunction{Name: "main", NumLocals: 10})
+
+	// $a['count'] += 2 on an undefined $a, then $a['count'] **= 2
+	instrs := []Instruction{
+		{Opcode: OpAssignDimOp, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpConst, Value: 1}, ExtendedValue: uint32(OpAdd)},
+		{Opcode: OpAssignDimOp, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpConst, Value: 1}, ExtendedValue: uint32(OpPow)},
+	}
+	for _, instr := range instrs {
+		if err := vm.dispatch(frame, instr); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	count, _ := frame.getLocal(0).ToArray().Get(types.NewString("count"))
+	if !count.Identical(types.NewInt(4)) {
+		t.Errorf("Expected $a['count'] to be 4, got %v", count)
+	}
+}
+
+func TestFetchList(t *testing.T) {
+	vm := New()
+	vm.SetScriptPath("test.php")
+
+	// $a = $arr[0]; $b = $arr[1][1]; $c = $arr['x'] (missing)
+	inner := types.NewEmptyArray()
+	inner.Append(types.NewInt(2))
+	inner.Append(types.NewInt(3))
+	arr := types.NewEmptyArray()
+	arr.Append(types.NewInt(1))
+	arr.Append(types.NewArray(inner))
+	vm.SetGlobal("arr", types.NewArray(arr))
+
+	err := vm.ExecuteScript(&Script{
+		Instructions: Instructions{
+			{Opcode: OpFetchListR, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 10}},
+			{Opcode: OpAssign, Op2: Operand{Type: OpTmpVar, Value: 10}, Result: Operand{Type: OpCV, Value: 1}},
+			{Opcode: OpFetchListR, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 10}},
+			{Opcode: OpFetchListR, Op1: Operand{Type: OpTmpVar, Value: 10}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 11}},
+			{Opcode: OpAssign, Op2: Operand{Type: OpTmpVar, Value: 11}, Result: Operand{Type: OpCV, Value: 2}},
+			{Opcode: OpFetchListR, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpTmpVar, Value: 10}},
+			{Opcode: OpAssign, Op2: Operand{Type: OpTmpVar, Value: 10}, Result: Operand{Type: OpCV, Value: 3}},
+			// Anything but an array destructures to null without a warning
+			{Opcode: OpFetchListR, Op1: Operand{Type: OpConst, Value: 3}, Op2: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 10}},
+		},
+		Constants: []interface{}{int64(0), int64(1), "x", "str"},
+		Variables: []string{"arr", "a", "b", "c"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a, _ := vm.GetGlobal("a")
+	b, _ := vm.GetGlobal("b")
+	c, _ := vm.GetGlobal("c")
+	if a.ToInt() != 1 || b.ToInt() != 3 || !c.IsNull() {
+		t.Errorf("Expected 1, 3, null; got %v, %v, %v", a, b, c)
+	}
+	if vm.GetOutput() != "\nWarning: Undefined array key \"x\" in test.php on line 0\n" {
+		t.Errorf("Expected one undefined key warning, got %q", vm.GetOutput())
+	}
+}
+
+func TestFetchListW_BindsElements(t *testing.T) {
+	vm := New()
+	frame := NewFrame(mainScript(nil))
+
+	// [[&$a]] = $arr; with $arr null: both levels are created
+	instrs := []Instruction{
+		{Opcode: OpFetchListW, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 10}},
+		{Opcode: OpFetchListW, Op1: Operand{Type: OpTmpVar, Value: 10}, Op2: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 11}},
+		{Opcode: OpAssignRef, Op1: Operand{Type: OpCV, Value: 1}, Op2: Operand{Type: OpTmpVar, Value: 11}},
+	}
+	vm.constants = []interface{}{int64(0)}
+	for _, instr := range instrs {
+		if err := vm.dispatch(frame, instr); err != nil {
+			t.Fatalf("%s: unexpected error: %v", instr.Opcode, err)
+		}
+	}
+
+	// Writing $a changes $arr[0][0]
+	frame.getLocal(1).Assign(types.NewInt(7))
+	outer := frame.getLocal(0).Deref().ToArray()
+	inner, _ := outer.Get(types.NewInt(0))
+	element, _ := inner.Deref().ToArray().Get(types.NewInt(0))
+	if element.Deref().ToInt() != 7 {
+		t.Errorf("Expected $arr[0][0] to be bound to $a, got %v", element.Deref())
+	}
+
+	// A scalar cannot be destructured by reference
+	frame.setLocal(0, types.NewInt(1))
+	if err := vm.dispatch(frame, instrs[0]); err == nil {
+		t.Error("Expected an error for a scalar container")
+	}
+}
@@ -0,0 +1,508 @@
+package vm
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// Include kinds, stored in the ExtendedValue of INCLUDE_OR_EVAL
+const (
+	IncludeKindEval        uint32 = 1 << 0
+	IncludeKindInclude     uint32 = 1 << 1
+	IncludeKindIncludeOnce uint32 = 1 << 2
+	IncludeKindRequire     uint32 = 1 << 3
+	IncludeKindRequireOnce uint32 = 1 << 4
+)
+
+// includeKindNames maps include kinds to the PHP construct names used in messages
+var includeKindNames = map[uint32]string{
+	IncludeKindEval:        "eval",
+	IncludeKindInclude:     "include",
+	IncludeKindIncludeOnce: "include_once",
+	IncludeKindRequire:     "require",
+	IncludeKindRequireOnce: "require_once",
+}
+
+// Script is a compiled PHP file ready to be executed or included
+type Script struct {
+	Path         string
+	Instructions Instructions
+	Constants    []interface{}
+	Variables    []string                // Global variable names, indexed by CV number
+	StrictTypes  bool                    // Compiled with declare(strict_types=1)
+	TryCatch     []types.TryCatchElement // Exception table of the main op array
+}
+
+// ScriptCompiler compiles the source of a PHP file. The VM cannot depend on
+// the compiler package, so the embedder provides one (compiler.CompileScript).
+type ScriptCompiler func(path string, source []byte) (*Script, error)
+
+// cachedScript is an opcode cache entry, invalidated when the file changes
+type cachedScript struct {
+	fn      *CompiledFunction
+	modTime time.Time
+	size    int64
+}
+
+// scriptCache is the opcode cache: the compiled files by real path. The
+// VMs running the requests of a Program share one, so it takes a lock.
+type scriptCache struct {
+	mu      sync.RWMutex
+	scripts map[string]*cachedScript
+}
+
+// newScriptCache creates an empty opcode cache
+func newScriptCache() *scriptCache {
+	return &scriptCache{scripts: make(map[string]*cachedScript)}
+}
+
+// lookup returns the cached op array of a file, unless the file changed
+// since it was compiled
+func (c *scriptCache) lookup(path string, info os.FileInfo) (*CompiledFunction, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cached, ok := c.scripts[path]
+	if !ok || !cached.modTime.Equal(info.ModTime()) || cached.size != info.Size() {
+		return nil, false
+	}
+	return cached.fn, true
+}
+
+// store caches the op array of a file
+func (c *scriptCache) store(path string, info os.FileInfo, fn *CompiledFunction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.scripts[path] = &cachedScript{fn: fn, modTime: info.ModTime(), size: info.Size()}
+}
+
+// SetScriptCompiler sets the compiler used for include and require
+func (vm *VM) SetScriptCompiler(compile ScriptCompiler) {
+	vm.compileScript = compile
+}
+
+// SetIncludePath sets the directories searched by include and require
+func (vm *VM) SetIncludePath(paths []string) {
+	vm.config.Set("include_path", strings.Join(paths, string(os.PathListSeparator)), runtime.INI_SYSTEM)
+}
+
+// IncludePath returns the directories searched by include and require
+func (vm *VM) IncludePath() []string {
+	return vm.includePath
+}
+
+// mount is a directory whose files are served from a file system other
+// than the disk
+type mount struct {
+	root     string
+	fsys     fs.FS
+	searched bool // Relative paths are searched in root
+}
+
+// Mount serves the files of fsys under the directory root to include and
+// require, ahead of the disk, as PHP serves the files of a phar archive.
+// A path under root resolves in fsys, and relative paths are searched in
+// root before the include_path.
+func (vm *VM) Mount(root string, fsys fs.FS) {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	vm.mounts = append(vm.mounts, mount{root: filepath.Clean(root), fsys: fsys, searched: true})
+}
+
+// ReplaceFile serves source to include and require in place of the file at
+// path, which need not exist. Unlike Mount, it leaves the other files of
+// the directory and the search for relative paths alone.
+func (vm *VM) ReplaceFile(path string, source []byte) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	file := &sourceFile{name: filepath.Base(path), source: source, modTime: time.Now()}
+	vm.mounts = append(vm.mounts, mount{root: filepath.Dir(path), fsys: file})
+}
+
+// sourceFile is a file system holding a single file, served by ReplaceFile
+type sourceFile struct {
+	name    string
+	source  []byte
+	modTime time.Time
+}
+
+// Open implements fs.FS
+func (f *sourceFile) Open(name string) (fs.File, error) {
+	if name != f.name {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &openSourceFile{Reader: bytes.NewReader(f.source), file: f}, nil
+}
+
+// openSourceFile is an open sourceFile
+type openSourceFile struct {
+	*bytes.Reader
+	file *sourceFile
+}
+
+func (f *openSourceFile) Stat() (fs.FileInfo, error) { return sourceFileInfo{f.file}, nil }
+func (f *openSourceFile) Close() error               { return nil }
+
+// sourceFileInfo describes a sourceFile
+type sourceFileInfo struct{ file *sourceFile }
+
+func (i sourceFileInfo) Name() string       { return i.file.name }
+func (i sourceFileInfo) Size() int64        { return int64(len(i.file.source)) }
+func (i sourceFileInfo) Mode() fs.FileMode  { return 0444 }
+func (i sourceFileInfo) ModTime() time.Time { return i.file.modTime }
+func (i sourceFileInfo) IsDir() bool        { return false }
+func (i sourceFileInfo) Sys() interface{}   { return nil }
+
+// mountedFile finds the file a path names in a mounted file system
+func (vm *VM) mountedFile(path string) (fs.FS, string, bool) {
+	if len(vm.mounts) == 0 {
+		return nil, "", false
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	for _, m := range vm.mounts {
+		rel, err := filepath.Rel(m.root, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		name := filepath.ToSlash(rel)
+		if info, err := fs.Stat(m.fsys, name); err == nil && !info.IsDir() {
+			return m.fsys, name, true
+		}
+	}
+	return nil, "", false
+}
+
+// IncludedFiles returns the real paths of all executed and included files,
+// in the order they were first loaded
+func (vm *VM) IncludedFiles() []string {
+	return vm.includedOrder
+}
+
+// ExecuteScript executes a compiled script as the main program, followed
+// by the shutdown sequence. A script ending with exit or die succeeds; its
+// status is available from ExitStatus.
+func (vm *VM) ExecuteScript(script *Script) error {
+	return vm.executeMain(scriptFunction(script), script.Path)
+}
+
+// executeMain runs the main op array of a script at path
+func (vm *VM) executeMain(main *CompiledFunction, path string) error {
+	if path != "" {
+		vm.SetScriptPath(path)
+		vm.markIncluded(realPath(path))
+	}
+
+	vm.startRequest()
+	vm.loadScript(main, path)
+	frame := NewFrame(main)
+	vm.bindGlobalScope(frame)
+	if err := vm.pushFrame(frame); err != nil {
+		return err
+	}
+	err := vm.run()
+	if isExit(err) {
+		err = nil
+	}
+	vm.result = frame.returnValue
+
+	// The shutdown sequence runs however the script ended
+	if shutdownErr := vm.Shutdown(); err == nil {
+		err = shutdownErr
+	}
+	if err != nil {
+		vm.exitStatus = 255
+	}
+	return err
+}
+
+// scriptFunction turns a script into a function running its main op array,
+// which keeps its own constant table
+func scriptFunction(script *Script) *CompiledFunction {
+	constants := script.Constants
+	if constants == nil {
+		constants = []interface{}{}
+	}
+
+	numLocals := len(script.Variables) + 100 // Room for temporaries
+	return &CompiledFunction{
+		Name:         "main",
+		Instructions: script.Instructions,
+		Constants:    constants,
+		NumLocals:    numLocals,
+		Variables:    script.Variables,
+		StrictTypes:  script.StrictTypes,
+		TryCatch:     script.TryCatch,
+		File:         script.Path,
+	}
+}
+
+// opIncludeOrEval executes include, include_once, require and require_once
+// Op1: path of the file
+// Result: return value of the included file (1 if it does not return,
+// true if an *_once file was already included, false if include failed)
+// ExtendedValue: include kind (IncludeKind*)
+func (vm *VM) opIncludeOrEval(frame *Frame, instr Instruction) error {
+	kind := instr.ExtendedValue
+	name := includeKindNames[kind]
+	if kind == IncludeKindEval || name == "" {
+		return fmt.Errorf("INCLUDE_OR_EVAL: unsupported kind %d", kind)
+	}
+
+	pathVal, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	path := pathVal.ToString()
+	required := kind == IncludeKindRequire || kind == IncludeKindRequireOnce
+	once := kind == IncludeKindIncludeOnce || kind == IncludeKindRequireOnce
+
+	setResult := func(value *types.Value) error {
+		if instr.Result.Type != OpUnused {
+			return vm.setOperandValue(frame, instr.Result, value)
+		}
+		return nil
+	}
+
+	resolved, found := vm.resolveIncludePath(path)
+	if !found {
+		if path == "" {
+			vm.warning("%s(): Filename cannot be empty", name)
+		} else {
+			vm.warning("%s(%s): Failed to open stream: No such file or directory", name, path)
+		}
+		includePath := strings.Join(vm.includePath, string(os.PathListSeparator))
+		if required {
+			return fmt.Errorf("%s(): Failed opening required '%s' (include_path='%s')", name, path, includePath)
+		}
+		vm.warning("%s(): Failed opening '%s' for inclusion (include_path='%s')", name, path, includePath)
+		return setResult(types.NewBool(false))
+	}
+
+	if once && vm.includedFiles[resolved] {
+		return setResult(types.NewBool(true))
+	}
+
+	fn, err := vm.compileFile(resolved)
+	if err != nil {
+		return err
+	}
+	vm.markIncluded(resolved)
+
+	result, err := vm.executeIncluded(frame, fn, resolved)
+	if err != nil {
+		return err
+	}
+	return setResult(result)
+}
+
+// executeIncluded runs an included file in the variable scope of frame
+func (vm *VM) executeIncluded(frame *Frame, fn *CompiledFunction, path string) (*types.Value, error) {
+	newFrame := NewFrame(fn)
+	newFrame.returnValue = nil
+	newFrame.thisObject = frame.thisObject
+	newFrame.currentClass = frame.currentClass
+	newFrame.calledClass = frame.calledClass
+
+	// Files included from the global scope run in it; otherwise they
+	// share variables with the including scope by name
+	if frame.globalScope {
+		vm.bindGlobalScope(newFrame)
+	}
+	callerSlots := make(map[string]int, len(frame.fn.Variables))
+	for i, name := range frame.fn.Variables {
+		callerSlots[name] = i
+	}
+	bindings := make(map[int]int)
+	for i, name := range fn.Variables {
+		if slot, ok := callerSlots[name]; ok && slot < len(frame.locals) {
+			bindings[i] = slot
+			newFrame.locals[i] = frame.locals[slot]
+		}
+	}
+
+	vm.loadScript(fn, path)
+	previousPath := vm.scriptPath
+	vm.scriptPath = path
+	defer func() {
+		vm.scriptPath = previousPath
+		for local, slot := range bindings {
+			frame.locals[slot] = newFrame.locals[local]
+		}
+	}()
+
+	if err := vm.pushFrame(newFrame); err != nil {
+		return nil, err
+	}
+	if err := vm.runFrame(newFrame); err != nil {
+		vm.popFrame()
+		return nil, err
+	}
+	vm.popFrame()
+
+	if newFrame.returnValue == nil {
+		return types.NewInt(1), nil
+	}
+	return newFrame.returnValue, nil
+}
+
+// compileFile compiles a file, reusing the cached opcodes while the file is unchanged
+func (vm *VM) compileFile(path string) (*CompiledFunction, error) {
+	fsys, name, mounted := vm.mountedFile(path)
+	var info os.FileInfo
+	var err error
+	if mounted {
+		info, err = fs.Stat(fsys, name)
+	} else {
+		info, err = os.Stat(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if fn, ok := vm.scriptCache.lookup(path, info); ok {
+		return fn, nil
+	}
+
+	if vm.compileScript == nil {
+		return nil, fmt.Errorf("cannot include '%s': no script compiler configured", path)
+	}
+
+	var source []byte
+	if mounted {
+		source, err = fs.ReadFile(fsys, name)
+	} else {
+		source, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := vm.compileScript(path, source)
+	if err != nil {
+		return nil, err
+	}
+
+	fn := scriptFunction(script)
+	vm.scriptCache.store(path, info, fn)
+	return fn, nil
+}
+
+// resolveIncludePath finds the file an include refers to and returns its real path.
+// Absolute paths and paths starting with ./ or ../ are used as given; other
+// paths are searched in the mounted directories, the include_path, then the
+// directory of the current script, then the working directory. Files of
+// mounted file systems are found ahead of those on disk.
+func (vm *VM) resolveIncludePath(path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+
+	var candidates []string
+	switch {
+	case filepath.IsAbs(path), strings.HasPrefix(path, "./"), strings.HasPrefix(path, "../"):
+		candidates = []string{path}
+	default:
+		for _, m := range vm.mounts {
+			if m.searched {
+				candidates = append(candidates, filepath.Join(m.root, path))
+			}
+		}
+		for _, dir := range vm.includePath {
+			candidates = append(candidates, filepath.Join(dir, path))
+		}
+		if vm.scriptPath != "" {
+			candidates = append(candidates, filepath.Join(filepath.Dir(vm.scriptPath), path))
+		}
+		candidates = append(candidates, path)
+	}
+
+	for _, candidate := range candidates {
+		if _, _, ok := vm.mountedFile(candidate); ok {
+			if abs, err := filepath.Abs(candidate); err == nil {
+				return abs, true
+			}
+			return filepath.Clean(candidate), true
+		}
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return realPath(candidate), true
+		}
+	}
+	return "", false
+}
+
+// markIncluded records a file for *_once checks and get_included_files()
+func (vm *VM) markIncluded(path string) {
+	if !vm.includedFiles[path] {
+		vm.includedFiles[path] = true
+		vm.includedOrder = append(vm.includedOrder, path)
+	}
+}
+
+// realPath returns the absolute path with symlinks resolved, like realpath()
+func realPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
+
+// ============================================================================
+// Include Builtins
+// ============================================================================
+
+// registerIncludeBuiltins registers the include_path and included file functions
+func (vm *VM) registerIncludeBuiltins() {
+	vm.RegisterBuiltin("get_include_path", builtinGetIncludePath)
+	vm.RegisterBuiltin("set_include_path", builtinSetIncludePath)
+	vm.RegisterBuiltin("get_included_files", builtinGetIncludedFiles)
+	vm.RegisterBuiltin("get_required_files", builtinGetIncludedFiles)
+}
+
+// get_include_path(): string
+func builtinGetIncludePath(vm *VM, args []*types.Value) (*types.Value, error) {
+	return types.NewString(strings.Join(vm.includePath, string(os.PathListSeparator))), nil
+}
+
+// set_include_path(string $include_path): string|false
+func builtinSetIncludePath(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("set_include_path() expects exactly 1 argument, %d given", len(args))
+	}
+	path := args[0].ToString()
+	if path == "" {
+		return types.NewBool(false), nil
+	}
+
+	old, err := vm.config.Set("include_path", path, runtime.INI_USER)
+	if err != nil {
+		return types.NewBool(false), nil
+	}
+	return types.NewString(old), nil
+}
+
+// get_included_files(): array
+func builtinGetIncludedFiles(vm *VM, args []*types.Value) (*types.Value, error) {
+	files := types.NewEmptyArray()
+	for _, file := range vm.includedOrder {
+		files.Append(types.NewString(file))
+	}
+	return types.NewArray(files), nil
+}
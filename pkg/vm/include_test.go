@@ -0,0 +1,289 @@
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// fakeCompiler serves precompiled scripts keyed by file content and counts compilations
+type fakeCompiler struct {
+	scripts  map[string]*Script
+	compiled int
+}
+
+func (f *fakeCompiler) compile(path string, source []byte) (*Script, error) {
+	f.compiled++
+	script := *f.scripts[string(source)]
+	script.Path = path
+	return &script, nil
+}
+
+// writeScript creates a PHP file and returns its real path
+func writeScript(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return realPath(path)
+}
+
+// includeInstr emits: T10 = <kind> constants[path]
+func includeInstr(kind uint32, path uint32) Instruction {
+	return Instruction{
+		Opcode:        OpIncludeOrEval,
+		Op1:           Operand{Type: OpConst, Value: path},
+		Result:        Operand{Type: OpTmpVar, Value: 10},
+		ExtendedValue: kind,
+	}
+}
+
+// mainScript builds a main function with the given global variables
+func mainScript(instrs Instructions, variables ...string) *CompiledFunction {
+	return &CompiledFunction{Name: "main", Instructions: instrs, NumLocals: 20, Variables: variables}
+}
+
+func TestInclude_SharesScopeAndReturnsValue(t *testing.T) {
+	dir := t.TempDir()
+	path := writeScript(t, dir, "lib.php", "lib")
+
+	// lib.php: $y = $x + 5; $x = 100; return $y;
+	compiler := &fakeCompiler{scripts: map[string]*Script{"lib": {
+		Instructions: Instructions{
+			{Opcode: OpAdd, Op1: Operand{Type: OpCV, Value: 1}, Op2: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpCV, Value: 0}},
+			{Opcode: OpAssign, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpCV, Value: 1}},
+			{Opcode: OpReturn, Op1: Operand{Type: OpCV, Value: 0}},
+		},
+		Constants: []interface{}{int64(5), int64(100)},
+		Variables: []string{"y", "x"},
+	}}}
+
+	vm := New()
+	vm.SetScriptCompiler(compiler.compile)
+	vm.constants = []interface{}{path}
+
+	fn := mainScript(Instructions{includeInstr(IncludeKindInclude, 0)}, "x")
+	frame := NewFrame(fn)
+	frame.setLocal(0, types.NewInt(10))
+	vm.pushFrame(frame)
+
+	if err := vm.runFrame(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result := frame.getLocal(fn.tempSlot(10)); result.ToInt() != 15 {
+		t.Errorf("Expected include to return 15, got %v", result)
+	}
+	if x := frame.getLocal(0); x.ToInt() != 100 {
+		t.Errorf("Expected $x to be updated to 100 in the including scope, got %v", x)
+	}
+	if vm.scriptPath != "" {
+		t.Errorf("Script path should be restored after include, got %q", vm.scriptPath)
+	}
+}
+
+func TestInclude_OnceAndOpcodeCache(t *testing.T) {
+	dir := t.TempDir()
+	path := writeScript(t, dir, "once.php", "once")
+
+	// once.php: echo "loaded;";
+	compiler := &fakeCompiler{scripts: map[string]*Script{"once": {
+		Instructions: Instructions{{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 0}}},
+		Constants:    []interface{}{"loaded;"},
+	}}}
+
+	vm := New()
+	vm.SetScriptCompiler(compiler.compile)
+	vm.constants = []interface{}{path}
+
+	instrs := Instructions{
+		includeInstr(IncludeKindIncludeOnce, 0),
+		includeInstr(IncludeKindRequireOnce, 0),
+		includeInstr(IncludeKindInclude, 0),
+	}
+	if err := runMain(vm, mainScript(instrs)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if vm.GetOutput() != "loaded;loaded;" {
+		t.Errorf("Expected file to run twice (once + plain include), got %q", vm.GetOutput())
+	}
+	if compiler.compiled != 1 {
+		t.Errorf("Expected file to be compiled once, compiled %d times", compiler.compiled)
+	}
+	if files := vm.IncludedFiles(); len(files) != 1 || files[0] != path {
+		t.Errorf("Expected included files [%s], got %v", path, files)
+	}
+}
+
+func TestInclude_SearchesIncludePathAndScriptDir(t *testing.T) {
+	libDir := t.TempDir()
+	scriptDir := t.TempDir()
+	writeScript(t, libDir, "a.php", "a")
+	writeScript(t, scriptDir, "b.php", "b")
+
+	compiler := &fakeCompiler{scripts: map[string]*Script{
+		"a": {Instructions: Instructions{{Opcode: OpReturn, Op1: Operand{Type: OpConst, Value: 0}}}, Constants: []interface{}{"from include_path"}},
+		"b": {Instructions: Instructions{{Opcode: OpReturn, Op1: Operand{Type: OpConst, Value: 0}}}, Constants: []interface{}{"from script dir"}},
+	}}
+
+	vm := New()
+	vm.SetScriptCompiler(compiler.compile)
+	vm.SetIncludePath([]string{libDir})
+	vm.SetScriptPath(filepath.Join(scriptDir, "index.php"))
+
+	for name, expected := range map[string]string{"a.php": "from include_path", "b.php": "from script dir"} {
+		resolved, ok := vm.resolveIncludePath(name)
+		if !ok {
+			t.Fatalf("%s was not found", name)
+		}
+		fn, err := vm.compileFile(resolved)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		result, err := vm.executeIncluded(NewFrame(mainScript(nil)), fn, resolved)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.ToString() != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, result.ToString())
+		}
+	}
+}
+
+func TestInclude_MountedFiles(t *testing.T) {
+	libDir := t.TempDir()
+	writeScript(t, libDir, "a.php", "disk")
+
+	returns := func(value string) *Script {
+		return &Script{Instructions: Instructions{{Opcode: OpReturn, Op1: Operand{Type: OpConst, Value: 0}}}, Constants: []interface{}{value}}
+	}
+	compiler := &fakeCompiler{scripts: map[string]*Script{
+		"disk":    returns("from disk"),
+		"mounted": returns("from mount"),
+		"nested":  returns("nested"),
+	}}
+
+	root := filepath.Join(t.TempDir(), "app.bundle")
+	vm := New()
+	vm.SetScriptCompiler(compiler.compile)
+	vm.SetIncludePath([]string{libDir})
+	vm.Mount(root, fstest.MapFS{
+		"a.php":     {Data: []byte("mounted")},
+		"lib/b.php": {Data: []byte("nested")},
+	})
+
+	for name, expected := range map[string]string{
+		"a.php":                          "from mount",
+		filepath.Join(root, "lib/b.php"): "nested",
+		filepath.Join(libDir, "a.php"):   "from disk",
+	} {
+		resolved, ok := vm.resolveIncludePath(name)
+		if !ok {
+			t.Fatalf("%s was not found", name)
+		}
+		fn, err := vm.compileFile(resolved)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		result, err := vm.executeIncluded(NewFrame(mainScript(nil)), fn, resolved)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.ToString() != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, result.ToString())
+		}
+	}
+
+	if _, ok := vm.resolveIncludePath(filepath.Join(root, "lib")); ok {
+		t.Error("A mounted directory should not be includable")
+	}
+}
+
+func TestInclude_ReplaceFile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeScript(t, dir, "autoload.php", "disk")
+	writeScript(t, dir, "other.php", "other")
+
+	returns := func(value string) *Script {
+		return &Script{Instructions: Instructions{{Opcode: OpReturn, Op1: Operand{Type: OpConst, Value: 0}}}, Constants: []interface{}{value}}
+	}
+	compiler := &fakeCompiler{scripts: map[string]*Script{
+		"disk":     returns("from disk"),
+		"other":    returns("other"),
+		"replaced": returns("replaced"),
+	}}
+
+	vm := New()
+	vm.SetScriptCompiler(compiler.compile)
+	vm.ReplaceFile(path, []byte("replaced"))
+
+	for name, expected := range map[string]string{path: "replaced", filepath.Join(dir, "other.php"): "other"} {
+		resolved, ok := vm.resolveIncludePath(name)
+		if !ok {
+			t.Fatalf("%s was not found", name)
+		}
+		fn, err := vm.compileFile(resolved)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		result, err := vm.executeIncluded(NewFrame(mainScript(nil)), fn, resolved)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.ToString() != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, result.ToString())
+		}
+	}
+
+	// The directory of a replaced file is not searched for relative paths
+	if _, ok := vm.resolveIncludePath("autoload.php"); ok {
+		t.Error("A relative path should not resolve to a replaced file")
+	}
+}
+
+func TestInclude_MissingFile(t *testing.T) {
+	vm := New()
+	vm.SetScriptPath("/app/index.php")
+	vm.constants = []interface{}{"missing.php"}
+
+	fn := mainScript(Instructions{includeInstr(IncludeKindInclude, 0)})
+	frame := NewFrame(fn)
+	vm.pushFrame(frame)
+	if err := vm.runFrame(frame); err != nil {
+		t.Fatalf("include of a missing file should not be fatal: %v", err)
+	}
+	if result := frame.getLocal(10); result.Type() != types.TypeBool || result.ToBool() {
+		t.Errorf("Expected include to return false, got %v", result)
+	}
+	if !strings.Contains(vm.GetOutput(), "Warning: include(missing.php): Failed to open stream: No such file or directory in /app/index.php") {
+		t.Errorf("Expected failed to open stream warning, got %q", vm.GetOutput())
+	}
+
+	vm = New()
+	vm.constants = []interface{}{"missing.php"}
+	err := runMain(vm, mainScript(Instructions{includeInstr(IncludeKindRequire, 0)}))
+	if err == nil || !strings.Contains(err.Error(), "Failed opening required 'missing.php'") {
+		t.Errorf("Expected require to fail, got %v", err)
+	}
+}
+
+func TestInclude_KeepsOwnConstants(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"main"}
+
+	fn := scriptFunction(&Script{
+		Instructions: Instructions{{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 0}}},
+		Constants:    []interface{}{"included"},
+	})
+
+	value, err := vm.getOperandValue(NewFrame(fn), fn.Instructions[0].Op1)
+	if err != nil || value.ToString() != "included" {
+		t.Errorf("Expected the script's constant 'included', got %v (%v)", value, err)
+	}
+}
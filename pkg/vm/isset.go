@@ -0,0 +1,157 @@
+package vm
+
+import (
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Static Property Isset/Unset
+// ============================================================================
+
+// opIssetIsemptyStaticProp handles isset(A::$prop) and empty(A::$prop)
+// Op1: class name, Op2: property name, ExtendedValue: IssetIsEmpty for empty()
+// An unknown class is not an error: the property is simply not set.
+func (vm *VM) opIssetIsemptyStaticProp(frame *Frame, instr Instruction) error {
+	className, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	propName, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+
+	var value *types.Value
+	exists := false
+	class, err := vm.staticClass(frame, className.ToString())
+	if err != nil {
+		return err
+	}
+	if class != nil {
+		value, exists = class.GetStaticProperty(propName.ToString())
+	}
+	return vm.setOperandValue(frame, instr.Result, issetOrEmpty(value, exists, instr))
+}
+
+// opUnsetStaticProp handles unset(A::$prop), which PHP forbids
+func (vm *VM) opUnsetStaticProp(frame *Frame, instr Instruction) error {
+	className, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	propName, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+
+	name := className.ToString()
+	class, err := vm.staticClass(frame, name)
+	if err != nil {
+		return err
+	}
+	if class == nil {
+		return vm.ThrowError("Error", "Class \"%s\" not found", strings.TrimPrefix(name, "\\"))
+	}
+	return vm.ThrowError("Error", "Attempt to unset static property %s::$%s", class.Name, propName.ToString())
+}
+
+// staticClass resolves the class of a static member access: self, parent,
+// static or a class name, which is autoloaded if needed. It returns nil for
+// unknown classes.
+func (vm *VM) staticClass(frame *Frame, name string) (*types.ClassEntry, error) {
+	switch strings.ToLower(name) {
+	case "self":
+		return frame.currentClass, nil
+	case "parent":
+		if frame.currentClass == nil {
+			return nil, nil
+		}
+		return frame.currentClass.ParentClass, nil
+	case "static":
+		if frame.calledClass != nil {
+			return frame.calledClass, nil
+		}
+		return frame.currentClass, nil
+	}
+
+	class, exists, err := vm.loadClass(name)
+	if err != nil || !exists {
+		return nil, err
+	}
+	return class, nil
+}
+
+// classReference resolves a class name used to call or instantiate a
+// class. Unlike staticClass, self, parent and static are errors outside a
+// class scope.
+func (vm *VM) classReference(frame *Frame, name string) (*types.ClassEntry, error) {
+	if isSpecialClassName(name) {
+		if frame.currentClass == nil {
+			return nil, vm.ThrowError("Error", "Cannot use \"%s\" when no class scope is active", strings.ToLower(name))
+		}
+		if strings.EqualFold(name, "parent") && frame.currentClass.ParentClass == nil {
+			return nil, vm.ThrowError("Error", "Cannot use \"parent\" when current class scope has no parent")
+		}
+	}
+	return vm.staticClass(frame, name)
+}
+
+// isSpecialClassName reports whether name is self, parent or static
+func isSpecialClassName(name string) bool {
+	switch strings.ToLower(name) {
+	case "self", "parent", "static":
+		return true
+	}
+	return false
+}
+
+// ============================================================================
+// Magic Property Methods
+// ============================================================================
+
+// magicCall identifies a magic method call on an object for a property,
+// so the method can access the property itself without calling itself again
+type magicCall struct {
+	obj      *types.Object
+	method   string
+	property string
+}
+
+// magicMethodOf returns a method of a class by name, whether it was
+// registered as a regular or a magic method
+func magicMethodOf(class *types.ClassEntry, name string) *types.MethodDef {
+	if class == nil {
+		return nil
+	}
+	if method, ok := class.GetMethod(name); ok {
+		return method
+	}
+	return class.GetMagicMethod(name)
+}
+
+// callMagic calls a method that the engine invokes implicitly, such as
+// __isset(), __set() or offsetExists(), with the property name or offset
+// followed by any other arguments, if the object's class defines it;
+// called is false when it does not, or when the same call for the same
+// property is already in progress (PHP's recursion guard).
+func (vm *VM) callMagic(obj *types.Object, name string, arg *types.Value, args ...*types.Value) (result *types.Value, called bool, err error) {
+	method := magicMethodOf(obj.ClassEntry, name)
+	if method == nil {
+		return nil, false, nil
+	}
+
+	guard := magicCall{obj: obj, method: name, property: arg.ToString()}
+	if vm.magicCalls[guard] {
+		return nil, false, nil
+	}
+	if vm.magicCalls == nil {
+		vm.magicCalls = make(map[magicCall]bool)
+	}
+	vm.magicCalls[guard] = true
+	defer delete(vm.magicCalls, guard)
+
+	result, err = vm.invokeTarget(vm.methodTarget(obj.ClassEntry, obj, method), append([]*types.Value{arg}, args...))
+	return result, true, err
+}
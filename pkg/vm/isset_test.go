@@ -0,0 +1,211 @@
+package vm
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// runIsset executes an ISSET_ISEMPTY_* instruction on the given operands,
+// held in temps 10 and 11, and returns its result
+func runIsset(t *testing.T, vm *VM, opcode Opcode, empty bool, container, key *types.Value) bool {
+	t.Helper()
+	frame := NewFrame(mainScript(nil))
+	frame.setLocal(10, container)
+	frame.setLocal(11, key)
+	instr := Instruction{
+		Opcode: opcode,
+		Op1:    Operand{Type: OpTmpVar, Value: 10},
+		Op2:    Operand{Type: OpTmpVar, Value: 11},
+		Result: Operand{Type: OpTmpVar, Value: 12},
+	}
+	if empty {
+		instr.ExtendedValue = IssetIsEmpty
+	}
+	if err := vm.dispatch(frame, instr); err != nil {
+		t.Fatalf("%s: unexpected error: %v", opcode, err)
+	}
+	return frame.getLocal(12).ToBool()
+}
+
+func TestIssetIsemptyCV(t *testing.T) {
+	tests := []struct {
+		value *types.Value
+		isset bool
+		empty bool
+	}{
+		{types.NewUndef(), false, true},
+		{types.NewNull(), false, true},
+		{types.NewString("0"), true, true},
+		{types.NewString(""), true, true},
+		{types.NewString("0.0"), true, false},
+		{types.NewArray(types.NewEmptyArray()), true, true},
+		{types.NewFloat(0), true, true},
+		{types.NewFloat(math.NaN()), true, false},
+		{types.NewReference(types.NewInt(1)), true, false},
+	}
+
+	vm := New()
+	for _, tt := range tests {
+		frame := NewFrame(mainScript(nil))
+		frame.setLocal(0, tt.value)
+		for _, empty := range []bool{false, true} {
+			instr := Instruction{Opcode: OpIssetIsemptyCV, Op1: Operand{Type: OpCV, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 10}}
+			want := tt.isset
+			if empty {
+				instr.ExtendedValue = IssetIsEmpty
+				want = tt.empty
+			}
+			if err := vm.dispatch(frame, instr); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := frame.getLocal(10).ToBool(); got != want {
+				t.Errorf("%v (empty: %v): got %v, want %v", tt.value, empty, got, want)
+			}
+		}
+	}
+}
+
+func TestIssetIsemptyDimObj(t *testing.T) {
+	vm := New()
+	arr := types.NewEmptyArray()
+	arr.Set(types.NewString("zero"), types.NewInt(0))
+	arr.Set(types.NewString("null"), types.NewNull())
+	array := types.NewArray(arr)
+	str := types.NewString("ab0")
+
+	tests := []struct {
+		container *types.Value
+		key       *types.Value
+		isset     bool
+		empty     bool
+	}{
+		{array, types.NewString("zero"), true, true},
+		{array, types.NewString("null"), false, true},
+		{array, types.NewString("missing"), false, true},
+		{str, types.NewInt(1), true, false},
+		{str, types.NewInt(-1), true, true},
+		{str, types.NewString("1"), true, false},
+		{str, types.NewString("01"), false, true},
+		{str, types.NewInt(3), false, true},
+		{types.NewInt(5), types.NewInt(0), false, true},
+	}
+	for _, tt := range tests {
+		if got := runIsset(t, vm, OpIssetIsemptyDimObj, false, tt.container, tt.key); got != tt.isset {
+			t.Errorf("isset(%v[%v]) = %v, want %v", tt.container, tt.key, got, tt.isset)
+		}
+		if got := runIsset(t, vm, OpIssetIsemptyDimObj, true, tt.container, tt.key); got != tt.empty {
+			t.Errorf("empty(%v[%v]) = %v, want %v", tt.container, tt.key, got, tt.empty)
+		}
+	}
+	if vm.GetOutput() != "" {
+		t.Errorf("Expected no notices, got %q", vm.GetOutput())
+	}
+}
+
+func TestIssetIsemptyPropObj_Magic(t *testing.T) {
+	vm := New()
+
+	// __isset() reports "virtual" as set, __get() returns "" for it
+	var calls []string
+	class := types.NewClassEntry("Magic")
+	addNativeMethod(class, "__isset", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		calls = append(calls, "__isset("+args[0].ToString()+")")
+		return types.NewBool(args[0].ToString() == "virtual"), nil
+	})
+	addNativeMethod(class, "__get", 1, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		calls = append(calls, "__get("+args[0].ToString()+")")
+		return types.NewString(""), nil
+	})
+	obj := types.NewObjectFromClass(class)
+	obj.Properties["secret"] = &types.Property{Value: types.NewInt(1), Visibility: types.VisibilityPrivate}
+	objVal := types.NewObject(obj)
+
+	if !runIsset(t, vm, OpIssetIsemptyPropObj, false, objVal, types.NewString("virtual")) {
+		t.Error("Expected isset() to use __isset()")
+	}
+	if !runIsset(t, vm, OpIssetIsemptyPropObj, true, objVal, types.NewString("virtual")) {
+		t.Error("Expected empty() to read the value with __get()")
+	}
+	if runIsset(t, vm, OpIssetIsemptyPropObj, false, objVal, types.NewString("secret")) {
+		t.Error("Expected an inaccessible property to be checked with __isset()")
+	}
+	want := "__isset(virtual) __isset(virtual) __get(virtual) __isset(secret)"
+	if got := strings.Join(calls, " "); got != want {
+		t.Errorf("Expected calls %q, got %q", want, got)
+	}
+}
+
+func TestUnsetDim_Nested(t *testing.T) {
+	vm := New()
+	frame := NewFrame(mainScript(nil))
+
+	// $a = ['x' => ['y' => 1, 'z' => 2]]; $b = $a; unset($a['x']['y']);
+	inner := types.NewEmptyArray()
+	inner.Set(types.NewString("y"), types.NewInt(1))
+	inner.Set(types.NewString("z"), types.NewInt(2))
+	outer := types.NewEmptyArray()
+	outer.Set(types.NewString("x"), types.NewArray(inner))
+	a := types.NewArray(outer)
+	frame.setLocal(0, a)
+	frame.setLocal(1, assignValue(a))
+	vm.constants = []interface{}{"x", "y", "missing"}
+
+	for _, instr := range []Instruction{
+		{Opcode: OpFetchDimUnset, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 10}},
+		{Opcode: OpUnsetDim, Op1: Operand{Type: OpTmpVar, Value: 10}, Op2: Operand{Type: OpConst, Value: 1}},
+		// Unsetting below a missing element creates nothing
+		{Opcode: OpFetchDimUnset, Op1: Operand{Type: OpCV, Value: 0}, Op2: Operand{Type: OpConst, Value: 2}, Result: Operand{Type: OpTmpVar, Value: 10}},
+		{Opcode: OpUnsetDim, Op1: Operand{Type: OpTmpVar, Value: 10}, Op2: Operand{Type: OpConst, Value: 1}},
+	} {
+		if err := vm.dispatch(frame, instr); err != nil {
+			t.Fatalf("%s: unexpected error: %v", instr.Opcode, err)
+		}
+	}
+
+	x, _ := frame.getLocal(0).ToArray().Get(types.NewString("x"))
+	if x.ToArray().HasKey(types.NewString("y")) || x.ToArray().Len() != 1 {
+		t.Errorf("Expected $a['x']['y'] to be unset, got %v", x)
+	}
+	if frame.getLocal(0).ToArray().Len() != 1 {
+		t.Errorf("Expected no element to be created for a missing key")
+	}
+	copied, _ := frame.getLocal(1).ToArray().Get(types.NewString("x"))
+	if !copied.ToArray().HasKey(types.NewString("y")) {
+		t.Errorf("Expected the copy $b to keep its elements")
+	}
+
+	// Strings offsets cannot be unset
+	frame.setLocal(2, types.NewString("abc"))
+	err := vm.dispatch(frame, Instruction{Opcode: OpUnsetDim, Op1: Operand{Type: OpCV, Value: 2}, Op2: Operand{Type: OpConst, Value: 0}})
+	if _, ok := err.(*ThrowableError); !ok {
+		t.Errorf("Expected an Error unsetting a string offset, got %v", err)
+	}
+}
+
+func TestStaticPropIssetUnset(t *testing.T) {
+	vm := New()
+	class := types.NewClassEntry("Counter")
+	class.StaticProperties["count"] = types.NewInt(0)
+	vm.classes["Counter"] = class
+
+	if !runIsset(t, vm, OpIssetIsemptyStaticProp, false, types.NewString("Counter"), types.NewString("count")) {
+		t.Error("Expected isset(Counter::$count) to be true")
+	}
+	if !runIsset(t, vm, OpIssetIsemptyStaticProp, true, types.NewString("Counter"), types.NewString("count")) {
+		t.Error("Expected empty(Counter::$count) to be true")
+	}
+	if runIsset(t, vm, OpIssetIsemptyStaticProp, false, types.NewString("Missing"), types.NewString("count")) {
+		t.Error("Expected isset() on an unknown class to be false")
+	}
+
+	frame := NewFrame(mainScript(nil))
+	vm.constants = []interface{}{"Counter", "count"}
+	err := vm.dispatch(frame, Instruction{Opcode: OpUnsetStaticProp, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}})
+	thrown, ok := err.(*ThrowableError)
+	if !ok || throwableProperty(thrown.Object, "message").ToString() != "Attempt to unset static property Counter::$count" {
+		t.Errorf("Expected an Error unsetting a static property, got %v", err)
+	}
+}
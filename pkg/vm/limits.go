@@ -0,0 +1,164 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"runtime/metrics"
+	"time"
+
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Resource Limits
+// ============================================================================
+
+// limitCheckInterval is the number of instructions between checks of the
+// time and memory limits and of the collector's root buffer
+const limitCheckInterval = 1024
+
+// setTimeLimit limits the execution time from now on, as
+// max_execution_time and set_time_limit() do; zero removes the limit.
+// The time is wall-clock time.
+func (vm *VM) setTimeLimit(seconds int64) {
+	vm.timeLimit = time.Duration(seconds) * time.Second
+	vm.deadline = time.Time{}
+	if seconds > 0 {
+		vm.deadline = time.Now().Add(vm.timeLimit)
+	}
+}
+
+// heapInUse returns the bytes of heap objects, live or not yet collected.
+// The Go heap is shared by all the VMs of the process.
+func heapInUse() int64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	return int64(sample[0].Value.Uint64())
+}
+
+// heapReserved returns the bytes the process obtained for its heap
+func heapReserved() int64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/total:bytes"}}
+	metrics.Read(sample)
+	return int64(sample[0].Value.Uint64())
+}
+
+// SetContext ties the execution to a context: once it is canceled or its
+// deadline passes, the script stops with the context's error. The context
+// is checked with the time limit, every limitCheckInterval instructions.
+func (vm *VM) SetContext(ctx context.Context) {
+	vm.ctx = ctx
+}
+
+// InstructionCount returns the number of instructions the VM has executed
+func (vm *VM) InstructionCount() uint64 {
+	return vm.instructionCount
+}
+
+// periodicChecks runs every limitCheckInterval instructions: it enforces
+// the limits and starts a cycle collection when one is due
+func (vm *VM) periodicChecks() error {
+	if vm.ctx != nil {
+		if err := vm.ctx.Err(); err != nil {
+			return fmt.Errorf("Execution interrupted: %w", err)
+		}
+	}
+	if !vm.deadline.IsZero() || vm.memoryLimit > 0 {
+		if err := vm.checkLimits(); err != nil {
+			return err
+		}
+	}
+	return vm.maybeCollectCycles()
+}
+
+// checkLimits raises the fatal error of an exceeded time or memory limit.
+// A limit is lifted once exceeded, so the shutdown functions can run.
+func (vm *VM) checkLimits() error {
+	if !vm.deadline.IsZero() && time.Now().After(vm.deadline) {
+		vm.deadline = time.Time{}
+		return vm.raiseError(runtime.E_ERROR, fmt.Sprintf("Maximum execution time of %d second%s exceeded",
+			int64(vm.timeLimit/time.Second), plural(int64(vm.timeLimit/time.Second))))
+	}
+	if vm.memoryLimit > 0 {
+		usage := heapInUse()
+		vm.peakMemory = max(vm.peakMemory, usage)
+		if usage > vm.memoryLimit {
+			limit := vm.memoryLimit
+			vm.memoryLimit = 0
+			return vm.raiseError(runtime.E_ERROR, fmt.Sprintf("Allowed memory size of %d bytes exhausted (tried to allocate %d bytes)", limit, usage-limit))
+		}
+	}
+	return nil
+}
+
+// plural returns the "s" of a count other than one
+func plural(n int64) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// setMemoryLimit applies memory_limit; a limit below the memory in use is
+// rejected
+func (vm *VM) setMemoryLimit(value string) error {
+	limit, err := runtime.ParseQuantity(value)
+	if err != nil {
+		return fmt.Errorf("Invalid \"memory_limit\" setting. %s", err)
+	}
+	if limit > 0 && heapInUse() > limit {
+		return fmt.Errorf("Failed to set memory limit to %d bytes (Current memory usage is %d bytes)", limit, heapInUse())
+	}
+	vm.memoryLimit = max(limit, 0)
+	return nil
+}
+
+// ============================================================================
+// Limit Builtins
+// ============================================================================
+
+// registerLimitBuiltins registers set_time_limit() and the memory usage
+// functions
+func (vm *VM) registerLimitBuiltins() {
+	vm.RegisterBuiltin("set_time_limit", builtinSetTimeLimit)
+	vm.RegisterBuiltin("memory_get_usage", builtinMemoryGetUsage)
+	vm.RegisterBuiltin("memory_get_peak_usage", builtinMemoryGetPeakUsage)
+	vm.RegisterBuiltin("memory_reset_peak_usage", builtinMemoryResetPeakUsage)
+}
+
+// set_time_limit(int $seconds): bool
+// The timer restarts with the new limit.
+func builtinSetTimeLimit(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("set_time_limit() expects exactly 1 argument, 0 given")
+	}
+	_, err := vm.config.Set("max_execution_time", args[0].Deref().ToString(), runtime.INI_USER)
+	return types.NewBool(err == nil), nil
+}
+
+// memory_get_usage(bool $real_usage = false): int
+func builtinMemoryGetUsage(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) > 0 && args[0].Deref().ToBool() {
+		return types.NewInt(heapReserved()), nil
+	}
+	usage := heapInUse()
+	vm.peakMemory = max(vm.peakMemory, usage)
+	return types.NewInt(usage), nil
+}
+
+// memory_get_peak_usage(bool $real_usage = false): int
+// The peak is sampled when the memory limit is checked and the usage read.
+func builtinMemoryGetPeakUsage(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) > 0 && args[0].Deref().ToBool() {
+		return types.NewInt(heapReserved()), nil
+	}
+	vm.peakMemory = max(vm.peakMemory, heapInUse())
+	return types.NewInt(vm.peakMemory), nil
+}
+
+// memory_reset_peak_usage(): void
+func builtinMemoryResetPeakUsage(vm *VM, args []*types.Value) (*types.Value, error) {
+	vm.peakMemory = heapInUse()
+	return types.NewNull(), nil
+}
@@ -305,7 +305,7 @@ const (
 	OpUnsetObj Opcode = 76
 
 	// ========================================
-	// Foreach Operations (77-78)
+	// Foreach Operations (77-78) and Exit (79)
 	// ========================================
 
 	// OpFeResetR - Reset foreach for read: foreach ($arr as $val)
@@ -314,7 +314,8 @@ const (
 	// OpFeFetchR - Fetch next foreach element for read
 	OpFeFetchR Opcode = 78
 
-	// 79 is missing in PHP opcodes
+	// OpExit - Terminate the script: exit/die with an optional status
+	OpExit Opcode = 79
 
 	// ========================================
 	// Fetch Operations - Read (80-82)
@@ -1005,6 +1006,7 @@ var opcodeNames = [OpcodeLast + 1]string{
 	OpUnsetObj:                       "UNSET_OBJ",
 	OpFeResetR:                       "FE_RESET_R",
 	OpFeFetchR:                       "FE_FETCH_R",
+	OpExit:                           "EXIT",
 	OpFetchR:                         "FETCH_R",
 	OpFetchDimR:                      "FETCH_DIM_R",
 	OpFetchObjR:                      "FETCH_OBJ_R",
@@ -292,7 +292,6 @@ func TestOpcodeSequence(t *testing.T) {
 	// These opcodes are intentionally missing in PHP's implementation
 	missingOpcodes := map[uint8]bool{
 		45: true, // Gap between JMPNZ and JMPZ_EX
-		79: true, // Gap between FE_FETCH_R and FETCH_R
 	}
 
 	for i := uint8(0); i <= OpcodeLast; i++ {
@@ -0,0 +1,244 @@
+package vm
+
+import (
+	"io"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Output Buffering
+// ============================================================================
+
+// Phases passed to output handlers, the values of the
+// PHP_OUTPUT_HANDLER_* constants
+const (
+	outputHandlerStart = 1 << iota // First invocation of the handler
+	outputHandlerClean             // Buffer discarded (ob_clean, ob_end_clean)
+	outputHandlerFlush             // Buffer flushed (ob_flush)
+	outputHandlerFinal             // Buffer closed (ob_end_flush, shutdown)
+)
+
+// outputBuffer is a level of output buffering opened by ob_start()
+type outputBuffer struct {
+	data     []byte
+	callback *types.Value // Output handler (nil for the default handler)
+	started  bool         // Whether the handler has been invoked
+}
+
+// process passes the buffered data through the output handler. A handler
+// returning false leaves the data unchanged.
+func (vm *VM) processBuffer(buffer *outputBuffer, phase int) (string, error) {
+	data := string(buffer.data)
+	if buffer.callback == nil {
+		return data, nil
+	}
+	if !buffer.started {
+		phase |= outputHandlerStart
+		buffer.started = true
+	}
+	result, err := vm.CallCallable(buffer.callback, []*types.Value{types.NewString(data), types.NewInt(int64(phase))})
+	if err != nil {
+		return "", err
+	}
+	if result := result.Deref(); result.IsBool() && !result.ToBool() {
+		return data, nil
+	}
+	return result.ToString(), nil
+}
+
+// flushBuffer passes the innermost buffer through its handler and writes
+// the result to the enclosing level; final closes the buffer
+func (vm *VM) flushBuffer(final bool) error {
+	n := len(vm.outputBuffers)
+	buffer := vm.outputBuffers[n-1]
+	phase := outputHandlerFlush
+	if final {
+		phase = outputHandlerFinal
+	}
+
+	output, err := vm.processBuffer(buffer, phase)
+	buffer.data = buffer.data[:0]
+	if final {
+		vm.outputBuffers = vm.outputBuffers[:n-1]
+	}
+	if err != nil {
+		return err
+	}
+
+	// The output goes to the enclosing level
+	if n > 1 {
+		parent := vm.outputBuffers[n-2]
+		parent.data = append(parent.data, output...)
+	} else {
+		vm.emitOutput([]byte(output))
+	}
+	return nil
+}
+
+// cleanBuffer discards the innermost buffer, informing its handler;
+// final closes the buffer
+func (vm *VM) cleanBuffer(final bool) error {
+	n := len(vm.outputBuffers)
+	buffer := vm.outputBuffers[n-1]
+	phase := outputHandlerClean
+	if final {
+		phase |= outputHandlerFinal
+		vm.outputBuffers = vm.outputBuffers[:n-1]
+	}
+	_, err := vm.processBuffer(buffer, phase)
+	buffer.data = buffer.data[:0]
+	return err
+}
+
+// endOutputBuffers flushes and closes all output buffers, as at the end of
+// the request
+func (vm *VM) endOutputBuffers() error {
+	var firstErr error
+	for len(vm.outputBuffers) > 0 {
+		if err := vm.flushBuffer(true); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// SetOutputWriter sends the output to w as the script produces it, instead
+// of collecting it for GetOutput. Output in ob_start() buffers reaches w
+// when the buffers are flushed.
+func (vm *VM) SetOutputWriter(w io.Writer) {
+	vm.outputWriter = w
+}
+
+// ============================================================================
+// Output Control Builtins
+// ============================================================================
+
+// registerOutputBuiltins registers the output control functions
+func (vm *VM) registerOutputBuiltins() {
+	vm.RegisterBuiltin("ob_start", builtinObStart)
+	vm.RegisterBuiltin("ob_get_contents", builtinObGetContents)
+	vm.RegisterBuiltin("ob_get_length", builtinObGetLength)
+	vm.RegisterBuiltin("ob_get_level", builtinObGetLevel)
+	vm.RegisterBuiltin("ob_flush", builtinObFlush)
+	vm.RegisterBuiltin("ob_clean", builtinObClean)
+	vm.RegisterBuiltin("ob_end_flush", builtinObEndFlush)
+	vm.RegisterBuiltin("ob_end_clean", builtinObEndClean)
+	vm.RegisterBuiltin("ob_get_flush", builtinObGetFlush)
+	vm.RegisterBuiltin("ob_get_clean", builtinObGetClean)
+}
+
+// ob_start(?callable $callback = null, int $chunk_size = 0, int $flags = PHP_OUTPUT_HANDLER_STDFLAGS): bool
+func builtinObStart(vm *VM, args []*types.Value) (*types.Value, error) {
+	buffer := &outputBuffer{}
+	if len(args) > 0 && !args[0].Deref().IsNull() {
+		if !vm.IsCallable(args[0]) {
+			vm.warning("ob_start(): %s is not a valid callback", args[0].Deref().ToString())
+			vm.notice("ob_start(): Failed to create buffer")
+			return types.NewBool(false), nil
+		}
+		buffer.callback = args[0].Deref()
+	}
+	vm.outputBuffers = append(vm.outputBuffers, buffer)
+	return types.NewBool(true), nil
+}
+
+// ob_get_contents(): string|false
+func builtinObGetContents(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(vm.outputBuffers) == 0 {
+		return types.NewBool(false), nil
+	}
+	return types.NewString(string(vm.outputBuffers[len(vm.outputBuffers)-1].data)), nil
+}
+
+// ob_get_length(): int|false
+func builtinObGetLength(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(vm.outputBuffers) == 0 {
+		return types.NewBool(false), nil
+	}
+	return types.NewInt(int64(len(vm.outputBuffers[len(vm.outputBuffers)-1].data))), nil
+}
+
+// ob_get_level(): int
+func builtinObGetLevel(vm *VM, args []*types.Value) (*types.Value, error) {
+	return types.NewInt(int64(len(vm.outputBuffers))), nil
+}
+
+// noBuffer reports a buffer operation without an open buffer
+func (vm *VM) noBuffer(fn, failure string) (*types.Value, error) {
+	vm.notice("%s(): %s", fn, failure)
+	return types.NewBool(false), nil
+}
+
+// ob_flush(): bool
+func builtinObFlush(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(vm.outputBuffers) == 0 {
+		return vm.noBuffer("ob_flush", "Failed to flush buffer. No buffer to flush")
+	}
+	if err := vm.flushBuffer(false); err != nil {
+		return nil, err
+	}
+	return types.NewBool(true), nil
+}
+
+// ob_clean(): bool
+func builtinObClean(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(vm.outputBuffers) == 0 {
+		return vm.noBuffer("ob_clean", "Failed to delete buffer. No buffer to delete")
+	}
+	if err := vm.cleanBuffer(false); err != nil {
+		return nil, err
+	}
+	return types.NewBool(true), nil
+}
+
+// ob_end_flush(): bool
+func builtinObEndFlush(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(vm.outputBuffers) == 0 {
+		return vm.noBuffer("ob_end_flush", "Failed to delete and flush buffer. No buffer to delete or flush")
+	}
+	if err := vm.flushBuffer(true); err != nil {
+		return nil, err
+	}
+	return types.NewBool(true), nil
+}
+
+// ob_end_clean(): bool
+func builtinObEndClean(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(vm.outputBuffers) == 0 {
+		return vm.noBuffer("ob_end_clean", "Failed to delete buffer. No buffer to delete")
+	}
+	if err := vm.cleanBuffer(true); err != nil {
+		return nil, err
+	}
+	return types.NewBool(true), nil
+}
+
+// ob_get_flush(): string|false
+func builtinObGetFlush(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(vm.outputBuffers) == 0 {
+		return vm.noBuffer("ob_get_flush", "Failed to delete and flush buffer. No buffer to delete or flush")
+	}
+	contents := string(vm.outputBuffers[len(vm.outputBuffers)-1].data)
+	if err := vm.flushBuffer(true); err != nil {
+		return nil, err
+	}
+	return types.NewString(contents), nil
+}
+
+// ob_get_clean(): string|false
+func builtinObGetClean(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(vm.outputBuffers) == 0 {
+		return types.NewBool(false), nil
+	}
+	contents := string(vm.outputBuffers[len(vm.outputBuffers)-1].data)
+	if err := vm.cleanBuffer(true); err != nil {
+		return nil, err
+	}
+	return types.NewString(contents), nil
+}
+
+// OutputBufferLevel returns the number of open output buffers
+func (vm *VM) OutputBufferLevel() int {
+	return len(vm.outputBuffers)
+}
@@ -0,0 +1,80 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+func TestOutputBuffering_Nesting(t *testing.T) {
+	vm := New()
+
+	builtinObStart(vm, nil)
+	vm.writeOutput([]byte("outer "))
+	builtinObStart(vm, nil)
+	vm.writeOutput([]byte("inner"))
+
+	if level, _ := builtinObGetLevel(vm, nil); level.ToInt() != 2 {
+		t.Errorf("Expected level 2, got %d", level.ToInt())
+	}
+	contents, _ := builtinObGetClean(vm, nil)
+	if contents.ToString() != "inner" {
+		t.Errorf("Expected inner buffer contents, got %q", contents.ToString())
+	}
+	if length, _ := builtinObGetLength(vm, nil); length.ToInt() != 6 {
+		t.Errorf("Expected the outer buffer length, got %d", length.ToInt())
+	}
+
+	builtinObEndFlush(vm, nil)
+	if vm.GetOutput() != "outer " {
+		t.Errorf("Expected flushed outer buffer, got %q", vm.GetOutput())
+	}
+	if result, _ := builtinObGetContents(vm, nil); result.ToBool() {
+		t.Errorf("ob_get_contents() without a buffer should return false")
+	}
+}
+
+func TestOutputBuffering_Handler(t *testing.T) {
+	vm := New()
+	var phases []int64
+	handler := goHandler(func(vm *VM, args []*types.Value) (*types.Value, error) {
+		phases = append(phases, args[1].ToInt())
+		return types.NewString("[" + args[0].ToString() + "]"), nil
+	})
+
+	builtinObStart(vm, []*types.Value{handler})
+	vm.writeOutput([]byte("a"))
+	builtinObFlush(vm, nil)
+	vm.writeOutput([]byte("b"))
+	builtinObClean(vm, nil)
+	vm.writeOutput([]byte("c"))
+	builtinObEndFlush(vm, nil)
+
+	if vm.GetOutput() != "[a][c]" {
+		t.Errorf("Expected handler output, got %q", vm.GetOutput())
+	}
+	expected := []int64{outputHandlerStart | outputHandlerFlush, outputHandlerClean, outputHandlerFinal}
+	if len(phases) != len(expected) {
+		t.Fatalf("Expected phases %v, got %v", expected, phases)
+	}
+	for i := range expected {
+		if phases[i] != expected[i] {
+			t.Errorf("Expected phases %v, got %v", expected, phases)
+			break
+		}
+	}
+}
+
+func TestOutputBuffering_NoBuffer(t *testing.T) {
+	vm := New()
+	vm.SetScriptPath("test.php")
+
+	result, err := builtinObEndClean(vm, nil)
+	if err != nil || result.ToBool() {
+		t.Fatalf("Expected false, got %v (%v)", result, err)
+	}
+	expected := "\nNotice: ob_end_clean(): Failed to delete buffer. No buffer to delete in test.php on line 0\n"
+	if vm.GetOutput() != expected {
+		t.Errorf("Expected %q, got %q", expected, vm.GetOutput())
+	}
+}
@@ -0,0 +1,205 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// ============================================================================
+// Opcode Profiling
+// ============================================================================
+
+// OpcodeStat holds the execution counters of one opcode within one function
+type OpcodeStat struct {
+	Function string        // Function the opcode executed in
+	Opcode   Opcode        // The opcode
+	Count    uint64        // Number of executions
+	Time     time.Duration // Cumulative time, excluding nested calls
+}
+
+// opcodeKey identifies a (function, opcode) counter
+type opcodeKey struct {
+	function string
+	opcode   Opcode
+}
+
+// OpcodeProfiler counts opcode executions and their cumulative time per function.
+// Time spent in calls made by an opcode (e.g. DO_FCALL) is attributed to the
+// callee's opcodes, not to the caller, so the report shows where the VM
+// actually spends its time.
+type OpcodeProfiler struct {
+	stats  map[opcodeKey]*OpcodeStat
+	nested time.Duration // Time consumed by nested dispatches of the current opcode
+	now    func() time.Time
+}
+
+// NewOpcodeProfiler creates an empty opcode profiler
+func NewOpcodeProfiler() *OpcodeProfiler {
+	return &OpcodeProfiler{
+		stats: make(map[opcodeKey]*OpcodeStat),
+		now:   time.Now,
+	}
+}
+
+// EnableOpcodeProfiling turns on per-opcode instrumentation
+func (vm *VM) EnableOpcodeProfiling() *OpcodeProfiler {
+	if vm.profiler == nil {
+		vm.profiler = NewOpcodeProfiler()
+	}
+	return vm.profiler
+}
+
+// OpcodeProfile returns the active opcode profiler (nil when disabled)
+func (vm *VM) OpcodeProfile() *OpcodeProfiler {
+	return vm.profiler
+}
+
+// record executes an instruction and accounts for it
+func (p *OpcodeProfiler) record(frame *Frame, instr Instruction, exec func(*Frame, Instruction) error) error {
+	outer := p.nested
+	p.nested = 0
+
+	start := p.now()
+	err := exec(frame, instr)
+	elapsed := p.now().Sub(start)
+
+	key := opcodeKey{function: frame.fn.Name, opcode: instr.Opcode}
+	stat, ok := p.stats[key]
+	if !ok {
+		stat = &OpcodeStat{Function: key.function, Opcode: key.opcode}
+		p.stats[key] = stat
+	}
+	stat.Count++
+	stat.Time += elapsed - p.nested
+
+	p.nested = outer + elapsed
+	return err
+}
+
+// Stats returns all per-function counters, sorted by execution count
+func (p *OpcodeProfiler) Stats() []OpcodeStat {
+	stats := make([]OpcodeStat, 0, len(p.stats))
+	for _, stat := range p.stats {
+		stats = append(stats, *stat)
+	}
+	sortStats(stats, false)
+	return stats
+}
+
+// Totals returns counters aggregated over all functions
+func (p *OpcodeProfiler) Totals() []OpcodeStat {
+	totals := make(map[Opcode]*OpcodeStat)
+	for _, stat := range p.stats {
+		total, ok := totals[stat.Opcode]
+		if !ok {
+			total = &OpcodeStat{Opcode: stat.Opcode}
+			totals[stat.Opcode] = total
+		}
+		total.Count += stat.Count
+		total.Time += stat.Time
+	}
+
+	stats := make([]OpcodeStat, 0, len(totals))
+	for _, total := range totals {
+		stats = append(stats, *total)
+	}
+	sortStats(stats, false)
+	return stats
+}
+
+// TopN returns the n most frequent (or, if byTime, the n slowest) opcodes
+func (p *OpcodeProfiler) TopN(n int, byTime bool) []OpcodeStat {
+	stats := p.Totals()
+	sortStats(stats, byTime)
+	if n > 0 && len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+// Reset clears all counters
+func (p *OpcodeProfiler) Reset() {
+	p.stats = make(map[opcodeKey]*OpcodeStat)
+	p.nested = 0
+}
+
+// sortStats orders stats by count (or time), breaking ties deterministically
+func sortStats(stats []OpcodeStat, byTime bool) {
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if byTime && a.Time != b.Time {
+			return a.Time > b.Time
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Function != b.Function {
+			return a.Function < b.Function
+		}
+		return a.Opcode < b.Opcode
+	})
+}
+
+// Report writes the top-n opcodes by count and by time, followed by a
+// per-function breakdown
+func (p *OpcodeProfiler) Report(w io.Writer, n int) {
+	var count uint64
+	var total time.Duration
+	functions := make(map[string]*OpcodeStat)
+	for _, stat := range p.stats {
+		count += stat.Count
+		total += stat.Time
+
+		fn, ok := functions[stat.Function]
+		if !ok {
+			fn = &OpcodeStat{Function: stat.Function}
+			functions[stat.Function] = fn
+		}
+		fn.Count += stat.Count
+		fn.Time += stat.Time
+	}
+
+	fmt.Fprintf(w, "Opcode profile: %d instructions executed in %v\n", count, total)
+
+	fmt.Fprintf(w, "\nTop %d opcodes by count:\n", n)
+	writeStatTable(w, p.TopN(n, false), total, "  ")
+
+	fmt.Fprintf(w, "\nTop %d opcodes by time:\n", n)
+	writeStatTable(w, p.TopN(n, true), total, "  ")
+
+	// Per-function breakdown, hottest functions first
+	fnStats := make([]OpcodeStat, 0, len(functions))
+	for _, fn := range functions {
+		fnStats = append(fnStats, *fn)
+	}
+	sortStats(fnStats, true)
+
+	byFunction := make(map[string][]OpcodeStat)
+	for _, stat := range p.Stats() {
+		byFunction[stat.Function] = append(byFunction[stat.Function], stat)
+	}
+
+	fmt.Fprintf(w, "\nPer function:\n")
+	for _, fn := range fnStats {
+		fmt.Fprintf(w, "  %s (%d instructions, %v)\n", fn.Function, fn.Count, fn.Time)
+		stats := byFunction[fn.Function]
+		if n > 0 && len(stats) > n {
+			stats = stats[:n]
+		}
+		writeStatTable(w, stats, total, "    ")
+	}
+}
+
+// writeStatTable writes opcode counters as an aligned table
+func writeStatTable(w io.Writer, stats []OpcodeStat, total time.Duration, indent string) {
+	fmt.Fprintf(w, "%s%-28s %12s %14s %7s\n", indent, "OPCODE", "COUNT", "TIME", "%TIME")
+	for _, stat := range stats {
+		percent := 0.0
+		if total > 0 {
+			percent = float64(stat.Time) * 100 / float64(total)
+		}
+		fmt.Fprintf(w, "%s%-28s %12d %14v %6.1f%%\n", indent, stat.Opcode, stat.Count, stat.Time, percent)
+	}
+}
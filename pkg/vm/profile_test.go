@@ -0,0 +1,123 @@
+package vm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a clock that advances by step on every reading
+func fakeClock(step time.Duration) func() time.Time {
+	now := time.Unix(0, 0)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestOpcodeProfiler_Counts(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{int64(1), int64(2)}
+	profiler := vm.EnableOpcodeProfiling()
+
+	mainFunc := &CompiledFunction{
+		Name: "main",
+		Instructions: Instructions{
+			{Opcode: OpAdd, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 0}},
+			{Opcode: OpAdd, Op1: Operand{Type: OpTmpVar, Value: 0}, Op2: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 0}},
+			{Opcode: OpEcho, Op1: Operand{Type: OpTmpVar, Value: 0}},
+		},
+		NumLocals: 10,
+	}
+
+	if err := vm.Execute(mainFunc.Instructions); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	top := profiler.TopN(1, false)
+	if len(top) != 1 || top[0].Opcode != OpAdd || top[0].Count != 2 {
+		t.Fatalf("Expected ADD x2 as top opcode, got %+v", top)
+	}
+	if len(profiler.Stats()) != 2 {
+		t.Errorf("Expected 2 distinct opcodes, got %d", len(profiler.Stats()))
+	}
+}
+
+func TestOpcodeProfiler_ExcludesNestedCalls(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"double", int64(4)}
+	vm.RegisterFunction("double", doubleFunction("double"))
+	profiler := vm.EnableOpcodeProfiling()
+	profiler.now = fakeClock(time.Millisecond)
+
+	mainFunc := &CompiledFunction{
+		Name: "main",
+		Instructions: Instructions{
+			{Opcode: OpInitFcall, Op2: Operand{Type: OpConst, Value: 0}},
+			{Opcode: OpSendVal, Op1: Operand{Type: OpConst, Value: 1}},
+			{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 0}},
+		},
+		NumLocals: 10,
+	}
+
+	frame := NewFrame(mainFunc)
+	vm.pushFrame(frame)
+	if err := vm.runFrame(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Each opcode reads the clock twice, so a leaf opcode takes exactly 1ms.
+	// DO_FCALL spans 5ms, of which the callee's two opcodes account for 2ms.
+	for _, stat := range profiler.Stats() {
+		expected := time.Millisecond
+		if stat.Opcode == OpDoFcall {
+			expected = 3 * time.Millisecond
+		}
+		if stat.Time != expected {
+			t.Errorf("%s in %s: expected %v self time, got %v", stat.Opcode, stat.Function, expected, stat.Time)
+		}
+	}
+
+	var functions []string
+	for _, stat := range profiler.Stats() {
+		if stat.Opcode == OpAdd {
+			functions = append(functions, stat.Function)
+		}
+	}
+	if len(functions) != 1 || functions[0] != "double" {
+		t.Errorf("ADD should be attributed to 'double', got %v", functions)
+	}
+}
+
+func TestOpcodeProfiler_Report(t *testing.T) {
+	profiler := NewOpcodeProfiler()
+	profiler.now = fakeClock(time.Microsecond)
+	frame := NewFrame(&CompiledFunction{Name: "main"})
+	noop := func(*Frame, Instruction) error { return nil }
+
+	for i := 0; i < 3; i++ {
+		profiler.record(frame, Instruction{Opcode: OpAdd}, noop)
+	}
+	profiler.record(frame, Instruction{Opcode: OpEcho}, noop)
+
+	var buf bytes.Buffer
+	profiler.Report(&buf, 5)
+	report := buf.String()
+
+	for _, expected := range []string{"4 instructions executed", "Top 5 opcodes by count", "Top 5 opcodes by time", "main (4 instructions"} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("Expected %q in report:\n%s", expected, report)
+		}
+	}
+	if strings.Index(report, "ADD") > strings.Index(report, "ECHO") {
+		t.Errorf("ADD should be listed before ECHO:\n%s", report)
+	}
+}
+
+func TestOpcodeProfiler_DisabledByDefault(t *testing.T) {
+	vm := New()
+	if vm.OpcodeProfile() != nil {
+		t.Error("Opcode profiling should be disabled by default")
+	}
+}
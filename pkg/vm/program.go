@@ -0,0 +1,66 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Programs and Requests
+// ============================================================================
+
+// Program is a compiled application shared by the requests running it:
+// its main script, the functions declared with it and the opcode cache of
+// the files it includes. It is not modified once created, except for the
+// opcode cache, which takes a lock, so a server can run each request in
+// its own VM (see NewRequest) on its own goroutine.
+//
+// What a request declares, defines or changes (classes, functions,
+// constants, globals, static variables, ini settings) lives in its VM and
+// is gone when the request ends.
+type Program struct {
+	path      string
+	main      *CompiledFunction
+	functions map[string]*CompiledFunction
+	compile   ScriptCompiler
+	cache     *scriptCache
+}
+
+// NewProgram creates a program running a compiled script, with the
+// functions compiled with it declared in every request. compile compiles
+// the files the script includes; it may be nil if the script includes none.
+func NewProgram(script *Script, compile ScriptCompiler, functions ...*CompiledFunction) *Program {
+	p := &Program{
+		path:      script.Path,
+		main:      scriptFunction(script),
+		functions: make(map[string]*CompiledFunction, len(functions)),
+		compile:   compile,
+		cache:     newScriptCache(),
+	}
+	for _, fn := range functions {
+		p.functions[types.InternString(strings.TrimPrefix(fn.Name, "\\"))] = fn
+	}
+	return p
+}
+
+// NewRequest creates the execution context of a request: a VM with its own
+// globals, classes, output and call stack, sharing the compiled code of the
+// program. A VM runs one request; concurrent requests need one each.
+func (p *Program) NewRequest() *VM {
+	vm := New()
+	vm.program = p
+	vm.compileScript = p.compile
+	vm.scriptCache = p.cache
+	return vm
+}
+
+// ExecuteProgram runs the main script of the program of a request created
+// by NewRequest, followed by the shutdown sequence, like ExecuteScript
+func (vm *VM) ExecuteProgram() error {
+	if vm.program == nil {
+		return fmt.Errorf("the VM does not run a program")
+	}
+	return vm.executeMain(vm.program.main, vm.program.path)
+}
@@ -0,0 +1,130 @@
+package vm
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// newTestProgram builds a program declaring a class whose constant is set
+// from the request's $id, passing it to a program function and including
+// a file:
+//
+//	class Counter { const ID = $id; }
+//	$r = twice(Counter::ID);
+//	echo $r, include 'lib.php';
+func newTestProgram(t *testing.T, compiled *atomic.Int32) *Program {
+	dir := t.TempDir()
+	path := writeScript(t, dir, "lib.php", "lib")
+
+	decl := newClassDecl("Counter")
+	decl.Class.Constants["ID"] = &types.ClassConstant{Name: "ID", Value: types.NewNull()}
+
+	id, r, t5, t6 := benchCV(0), benchCV(1), Operand{Type: OpTmpVar, Value: 5}, Operand{Type: OpTmpVar, Value: 6}
+	script := &Script{
+		Instructions: Instructions{
+			{Opcode: OpDeclareClass, Op1: benchConst(0)},
+			{Opcode: OpDeclareConst, Op1: benchConst(1), Op2: benchConst(2), Result: id, ExtendedValue: DeclareConstClass},
+			{Opcode: OpFetchClassConstant, Op1: benchConst(1), Op2: benchConst(2), Result: t5},
+			{Opcode: OpInitFcall, Op2: benchConst(3), ExtendedValue: 1},
+			{Opcode: OpSendVal, Op1: t5},
+			{Opcode: OpDoFcall, Result: r},
+			{Opcode: OpIncludeOrEval, Op1: benchConst(4), Result: t6, ExtendedValue: IncludeKindInclude},
+			{Opcode: OpEcho, Op1: r},
+			{Opcode: OpEcho, Op1: t6},
+		},
+		Constants: []interface{}{decl, "Counter", "ID", "twice", path},
+		Variables: []string{"id", "r"},
+	}
+
+	// function twice($n) { return $n + $n; }
+	n, t1 := benchCV(0), Operand{Type: OpTmpVar, Value: 1}
+	twice := &CompiledFunction{
+		Name:      "twice",
+		NumParams: 1,
+		NumLocals: 4,
+		Variables: []string{"n"},
+		Instructions: Instructions{
+			{Opcode: OpRecv, Op1: benchConst(0), Result: n},
+			{Opcode: OpAdd, Op1: n, Op2: n, Result: t1},
+			{Opcode: OpReturn, Op1: t1},
+		},
+	}
+
+	// lib.php: return "!";
+	compile := func(path string, source []byte) (*Script, error) {
+		compiled.Add(1)
+		return &Script{
+			Path:         path,
+			Instructions: Instructions{{Opcode: OpReturn, Op1: benchConst(0)}},
+			Constants:    []interface{}{"!"},
+		}, nil
+	}
+	return NewProgram(script, compile, twice)
+}
+
+func TestProgram_ConcurrentRequests(t *testing.T) {
+	var compiled atomic.Int32
+	program := newTestProgram(t, &compiled)
+
+	const workers, requests = 8, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*requests)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < requests; i++ {
+				id := int64(w*requests + i)
+				request := program.NewRequest()
+				request.SetGlobal("id", types.NewInt(id))
+				if err := request.ExecuteProgram(); err != nil {
+					errs <- fmt.Errorf("request %d: %v", id, err)
+					continue
+				}
+				if got, want := request.GetOutput(), fmt.Sprintf("%d!", 2*id); got != want {
+					errs <- fmt.Errorf("request %d: expected output %q, got %q", id, want, got)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if n := compiled.Load(); n < 1 || n > workers {
+		t.Errorf("The included file should be compiled once per racing request at most, got %d compilations", n)
+	}
+}
+
+func TestProgram_RequestsAreIsolated(t *testing.T) {
+	var compiled atomic.Int32
+	program := newTestProgram(t, &compiled)
+
+	first := program.NewRequest()
+	if err := first.ExecuteProgram(); err != nil {
+		t.Fatalf("ExecuteProgram() error: %v", err)
+	}
+	if _, ok := first.lookupClass("Counter"); !ok {
+		t.Fatal("The request should declare Counter")
+	}
+
+	// The class declared by the first request is not declared in the
+	// second, which can declare it again
+	second := program.NewRequest()
+	if err := second.ExecuteProgram(); err != nil {
+		t.Fatalf("A second request failed: %v", err)
+	}
+	if compiled.Load() != 1 {
+		t.Errorf("Requests should share the opcode cache, got %d compilations", compiled.Load())
+	}
+
+	if err := New().ExecuteProgram(); err == nil {
+		t.Error("A VM without a program should not execute one")
+	}
+}
@@ -0,0 +1,180 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Exit
+// ============================================================================
+
+// ExitError unwinds the call stack when the script calls exit or die. It is
+// not a PHP exception: catch and finally blocks are skipped, as in PHP 8,
+// while the shutdown sequence still runs.
+type ExitError struct {
+	Status int
+}
+
+// Error describes the exit
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit(%d)", e.Status)
+}
+
+// isExit reports whether err is the unwinding of exit or die
+func isExit(err error) bool {
+	var exit *ExitError
+	return errors.As(err, &exit)
+}
+
+// ExitStatus returns the process exit status of the script: the status
+// passed to exit, 255 after an uncaught exception or fatal error, else 0
+func (vm *VM) ExitStatus() int {
+	return vm.exitStatus
+}
+
+// Result returns the value the main script returned, null if it returned
+// none or has not run
+func (vm *VM) Result() *types.Value {
+	if vm.result == nil {
+		return types.NewNull()
+	}
+	return vm.result
+}
+
+// opExit terminates the script. A string status is printed and exits with
+// status 0, any other value is the exit status.
+// Op1: status (unused for a bare exit)
+func (vm *VM) opExit(frame *Frame, instr Instruction) error {
+	status := 0
+	if instr.Op1.Type != OpUnused {
+		value, err := vm.getOperandValue(frame, instr.Op1)
+		if err != nil {
+			return err
+		}
+		value = value.Deref()
+		if value.IsString() {
+			vm.writeOutput([]byte(value.ToString()))
+		} else {
+			status = int(value.ToInt())
+		}
+	}
+	vm.exitStatus = status
+	return &ExitError{Status: status}
+}
+
+// ============================================================================
+// Shutdown Sequence
+// ============================================================================
+
+// shutdownFunction is a callback registered by register_shutdown_function()
+type shutdownFunction struct {
+	callback *types.Value
+	args     []*types.Value
+}
+
+// destructorOf returns the __destruct method of a class, if it has one
+func destructorOf(class *types.ClassEntry) *types.MethodDef {
+	return magicMethodOf(class, "__destruct")
+}
+
+// trackDestructor records a new object whose class has a destructor, so the
+// shutdown sequence can destroy it if it is still alive
+func (vm *VM) trackDestructor(obj *types.Object) {
+	if destructorOf(obj.ClassEntry) != nil {
+		vm.destructibles = append(vm.destructibles, obj)
+	}
+}
+
+// destruct calls an object's destructor, once
+func (vm *VM) destruct(obj *types.Object) error {
+	if obj.IsDestroyed {
+		return nil
+	}
+	obj.IsDestroyed = true
+	method := destructorOf(obj.ClassEntry)
+	if method == nil {
+		return nil
+	}
+	_, err := vm.invokeTarget(vm.methodTarget(obj.ClassEntry, obj, method), nil)
+	return err
+}
+
+// Shutdown runs the end of request sequence, as PHP does after the script
+// finishes, calls exit() or dies of an uncaught exception: the shutdown
+// functions are called in registration order (including ones they register
+// themselves), then the destructors of the objects still alive in creation
+// order, then the open output buffers are flushed and finally an active
+// session is written. An exception
+// stops the step it occurs in but not the later ones; the first error is
+// returned. Shutdown runs once, later calls do nothing.
+func (vm *VM) Shutdown() error {
+	if vm.shutDown {
+		return nil
+	}
+	vm.shutDown = true
+
+	// Whatever the script left on the call stack is abandoned
+	for vm.frameIndex >= 0 {
+		vm.popFrame()
+	}
+
+	var firstErr error
+	record := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for i := 0; i < len(vm.shutdownFunctions); i++ {
+		fn := vm.shutdownFunctions[i]
+		if _, err := vm.CallCallable(fn.callback, fn.args); err != nil {
+			// exit in a shutdown function skips the remaining ones
+			if !isExit(err) {
+				record(err)
+			}
+			break
+		}
+	}
+
+	for i := 0; i < len(vm.destructibles); i++ {
+		if err := vm.destruct(vm.destructibles[i]); err != nil {
+			if !isExit(err) {
+				record(err)
+			}
+			break
+		}
+	}
+	vm.destructibles = nil
+
+	record(vm.endOutputBuffers())
+	record(vm.closeSession())
+	return firstErr
+}
+
+// ============================================================================
+// Shutdown Builtins
+// ============================================================================
+
+// registerShutdownBuiltins registers register_shutdown_function()
+func (vm *VM) registerShutdownBuiltins() {
+	vm.RegisterBuiltin("register_shutdown_function", builtinRegisterShutdownFunction)
+}
+
+// register_shutdown_function(callable $callback, mixed ...$args): void
+func builtinRegisterShutdownFunction(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("register_shutdown_function() expects at least 1 argument, 0 given")
+	}
+	if !vm.IsCallable(args[0]) {
+		return nil, vm.ThrowError("TypeError", "register_shutdown_function(): Argument #1 ($callback) must be a valid callback, %s given",
+			args[0].Deref().TypeName())
+	}
+	vm.shutdownFunctions = append(vm.shutdownFunctions, shutdownFunction{
+		callback: args[0].Deref(),
+		args:     append([]*types.Value(nil), args[1:]...),
+	})
+	return types.NewNull(), nil
+}
@@ -0,0 +1,193 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// newDestructibleClass creates a class whose destructor echoes "~<name>;"
+func newDestructibleClass(vm *VM, name string) *types.ClassEntry {
+	class := types.NewClassEntry(name)
+	addNativeMethod(class, "__destruct", 0, func(vm *VM, this *types.Object, args []*types.Value) (*types.Value, error) {
+		vm.writeOutput([]byte("~" + this.ClassName + ";"))
+		return types.NewNull(), nil
+	})
+	vm.classes[name] = class
+	return class
+}
+
+// echoCallback returns a callable echoing its arguments, each followed by ";"
+func echoCallback() *types.Value {
+	return goHandler(func(vm *VM, args []*types.Value) (*types.Value, error) {
+		for _, arg := range args {
+			vm.writeOutput([]byte(arg.ToString() + ";"))
+		}
+		return types.NewNull(), nil
+	})
+}
+
+func TestShutdown_Order(t *testing.T) {
+	vm := New()
+	newDestructibleClass(vm, "A")
+	newDestructibleClass(vm, "B")
+
+	// A shutdown function registered during shutdown still runs
+	late := goHandler(func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return builtinRegisterShutdownFunction(vm, []*types.Value{echoCallback(), types.NewString("late")})
+	})
+	builtinRegisterShutdownFunction(vm, []*types.Value{echoCallback(), types.NewString("first")})
+	builtinRegisterShutdownFunction(vm, []*types.Value{late})
+	builtinRegisterShutdownFunction(vm, []*types.Value{echoCallback(), types.NewString("second")})
+
+	// new A; new B; with output still buffered when the script ends
+	builtinObStart(vm, nil)
+	err := vm.ExecuteScript(&Script{
+		Instructions: Instructions{
+			{Opcode: OpNew, Op1: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 10}},
+			{Opcode: OpNew, Op1: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 11}},
+		},
+		Constants: []interface{}{"A", "B"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "first;second;late;~A;~B;"
+	if vm.GetOutput() != expected {
+		t.Errorf("Expected %q, got %q", expected, vm.GetOutput())
+	}
+	if vm.OutputBufferLevel() != 0 {
+		t.Errorf("Output buffers should be closed at shutdown")
+	}
+
+	// Shutdown runs once
+	if err := vm.Shutdown(); err != nil || vm.GetOutput() != expected {
+		t.Errorf("Second shutdown should do nothing, got %q (%v)", vm.GetOutput(), err)
+	}
+}
+
+func TestShutdown_AfterUncaughtException(t *testing.T) {
+	vm := New()
+	class := newDestructibleClass(vm, "A")
+
+	// An object destroyed before shutdown is not destroyed again
+	destroyed := types.NewObjectFromClass(class)
+	vm.trackDestructor(destroyed)
+	vm.destruct(destroyed)
+	vm.ClearOutput()
+
+	builtinRegisterShutdownFunction(vm, []*types.Value{echoCallback(), types.NewString("shutdown")})
+
+	// new A; throw new Exception;
+	err := vm.ExecuteScript(&Script{
+		Instructions: Instructions{
+			{Opcode: OpNew, Op1: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 10}},
+			{Opcode: OpNew, Op1: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 11}},
+			{Opcode: OpThrow, Op1: Operand{Type: OpTmpVar, Value: 11}},
+		},
+		Constants: []interface{}{"A", "Exception"},
+	})
+	if _, ok := err.(*ThrowableError); !ok {
+		t.Fatalf("Expected the uncaught exception, got %v", err)
+	}
+	if vm.GetOutput() != "shutdown;~A;" {
+		t.Errorf("Expected the shutdown sequence to run, got %q", vm.GetOutput())
+	}
+}
+
+func TestRegisterShutdownFunction_InvalidCallback(t *testing.T) {
+	vm := New()
+	_, err := builtinRegisterShutdownFunction(vm, []*types.Value{types.NewString("no_such_function")})
+	if thrown, ok := err.(*ThrowableError); !ok || thrown.Object.ClassEntry.Name != "TypeError" {
+		t.Errorf("Expected TypeError, got %v", err)
+	}
+}
+
+func TestExit_StatusAndShutdown(t *testing.T) {
+	vm := New()
+	builtinRegisterShutdownFunction(vm, []*types.Value{echoCallback(), types.NewString("shutdown")})
+
+	// exit(3); echo "unreachable";
+	err := vm.ExecuteScript(&Script{
+		Instructions: Instructions{
+			{Opcode: OpExit, Op1: Operand{Type: OpConst, Value: 0}},
+			{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 1}},
+		},
+		Constants: []interface{}{int64(3), "unreachable"},
+	})
+	if err != nil {
+		t.Fatalf("exit should end the script normally, got %v", err)
+	}
+	if vm.ExitStatus() != 3 {
+		t.Errorf("Expected exit status 3, got %d", vm.ExitStatus())
+	}
+	if vm.GetOutput() != "shutdown;" {
+		t.Errorf("Expected only the shutdown output, got %q", vm.GetOutput())
+	}
+}
+
+func TestExit_Message(t *testing.T) {
+	vm := New()
+
+	// A shutdown function calling exit skips the remaining ones
+	exiting := goHandler(func(vm *VM, args []*types.Value) (*types.Value, error) {
+		return nil, &ExitError{Status: 0}
+	})
+	builtinRegisterShutdownFunction(vm, []*types.Value{exiting})
+	builtinRegisterShutdownFunction(vm, []*types.Value{echoCallback(), types.NewString("skipped")})
+
+	// die("bye");
+	err := vm.ExecuteScript(&Script{
+		Instructions: Instructions{
+			{Opcode: OpExit, Op1: Operand{Type: OpConst, Value: 0}},
+		},
+		Constants: []interface{}{"bye"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vm.GetOutput() != "bye" || vm.ExitStatus() != 0 {
+		t.Errorf("Expected 'bye' with status 0, got %q (%d)", vm.GetOutput(), vm.ExitStatus())
+	}
+}
+
+func TestExit_SkipsFinally(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"finally;"}
+
+	// try { exit; } finally { echo "finally;"; }
+	fn := &CompiledFunction{
+		Name: "main",
+		Instructions: Instructions{
+			{Opcode: OpExit},
+			{Opcode: OpFastCall, Op1: Operand{Value: 3}},
+			{Opcode: OpJmp, Op1: Operand{Value: 5}},
+			{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 0}},
+			{Opcode: OpFastRet},
+		},
+		NumLocals: 10,
+		TryCatch:  []types.TryCatchElement{{TryOp: 0, FinallyOp: 3, FinallyEnd: 4}},
+	}
+
+	if err := runMain(vm, fn); !isExit(err) {
+		t.Fatalf("Expected exit to unwind the script, got %v", err)
+	}
+	if vm.GetOutput() != "" {
+		t.Errorf("finally should not run on exit, got %q", vm.GetOutput())
+	}
+}
+
+func TestExecuteScript_UncaughtExitStatus(t *testing.T) {
+	vm := New()
+	err := vm.ExecuteScript(&Script{
+		Instructions: Instructions{
+			{Opcode: OpNew, Op1: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 10}},
+			{Opcode: OpThrow, Op1: Operand{Type: OpTmpVar, Value: 10}},
+		},
+		Constants: []interface{}{"Exception"},
+	})
+	if err == nil || vm.ExitStatus() != 255 {
+		t.Errorf("Expected status 255 after an uncaught exception, got %d (%v)", vm.ExitStatus(), err)
+	}
+}
@@ -0,0 +1,159 @@
+package vm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Core Function Signatures
+// ============================================================================
+
+// coreSignatures declares the parameters of core functions implemented in
+// Go, in PHP's notation. Calls are checked against them like those of
+// DeclareBuiltin functions, in the caller's typing mode: strlen(5) throws
+// a TypeError under strict_types=1, while in coercive mode 5 is passed as
+// "5", a Stringable object as its string and null, with a deprecation,
+// as "".
+var coreSignatures = []string{
+	"strlen(string $string): int",
+	"substr(string $string, int $offset, ?int $length = null): string",
+	"str_contains(string $haystack, string $needle): bool",
+	"str_starts_with(string $haystack, string $needle): bool",
+	"str_ends_with(string $haystack, string $needle): bool",
+	"substr_count(string $haystack, string $needle, int $offset = 0, ?int $length = null): int",
+	"strtr(string $string, array|string $from, ?string $to = null): string",
+	"str_word_count(string $string, int $format = 0, ?string $characters = null): array|int",
+	"levenshtein(string $string1, string $string2, int $insertion_cost = 1, int $replacement_cost = 1, int $deletion_cost = 1): int",
+	"soundex(string $string): string",
+	"metaphone(string $string, int $max_phonemes = 0): string",
+	"quoted_printable_encode(string $string): string",
+	"quoted_printable_decode(string $string): string",
+	"convert_uuencode(string $string): string",
+	"convert_uudecode(string $string): string|false",
+	"sprintf(string $format, mixed ...$values): string",
+	"vsprintf(string $format, array $values): string",
+	"printf(string $format, mixed ...$values): int",
+	"vprintf(string $format, array $values): int",
+
+	"abs(int|float $num): int|float",
+	"ceil(int|float $num): float",
+	"floor(int|float $num): float",
+	"round(int|float $num, int $precision = 0, int $mode = PHP_ROUND_HALF_UP): float",
+	"min(mixed $value, mixed ...$values): mixed",
+	"max(mixed $value, mixed ...$values): mixed",
+	"pow(mixed $num, mixed $exponent): object|int|float",
+	"sqrt(float $num): float",
+	"fmod(float $num1, float $num2): float",
+	"fdiv(float $num1, float $num2): float",
+	"intdiv(int $num1, int $num2): int",
+	"hypot(float $x, float $y): float",
+	"pi(): float",
+	"sin(float $num): float",
+	"cos(float $num): float",
+	"tan(float $num): float",
+	"asin(float $num): float",
+	"acos(float $num): float",
+	"atan(float $num): float",
+	"atan2(float $y, float $x): float",
+	"deg2rad(float $num): float",
+	"rad2deg(float $num): float",
+	"exp(float $num): float",
+	"log(float $num, float $base = M_E): float",
+	"log10(float $num): float",
+	"log1p(float $num): float",
+	"expm1(float $num): float",
+	"is_nan(float $num): bool",
+	"is_finite(float $num): bool",
+	"is_infinite(float $num): bool",
+	"number_format(float $num, int $decimals = 0, ?string $decimal_separator = \".\", ?string $thousands_separator = \",\"): string",
+	"base_convert(string $num, int $from_base, int $to_base): string",
+	"bindec(string $binary_string): int|float",
+	"octdec(string $octal_string): int|float",
+	"hexdec(string $hex_string): int|float",
+	"decbin(int $num): string",
+	"decoct(int $num): string",
+	"dechex(int $num): string",
+	"getrandmax(): int",
+	"mt_getrandmax(): int",
+	"random_int(int $min, int $max): int",
+}
+
+// declareCoreSignatures declares the signatures of coreSignatures for the
+// functions registered
+func (vm *VM) declareCoreSignatures() {
+	for _, declaration := range coreSignatures {
+		name, signature := vm.parseSignature(declaration)
+		if _, ok := vm.builtins[name]; ok {
+			vm.signatures[name] = signature
+		}
+	}
+}
+
+// parseSignature parses a declaration of coreSignatures. Defaults are
+// literals or constants; a malformed declaration panics.
+func (vm *VM) parseSignature(declaration string) (string, *builtinSignature) {
+	open, closing := strings.IndexByte(declaration, '('), strings.LastIndexByte(declaration, ')')
+	if open < 0 || closing < open {
+		panic(fmt.Sprintf("malformed signature %q", declaration))
+	}
+	signature := &builtinSignature{
+		params:     []*types.ParameterDef{},
+		returnType: strings.TrimPrefix(declaration[closing+1:], ": "),
+		core:       true,
+	}
+	for _, field := range splitParameters(declaration[open+1 : closing]) {
+		param := &types.ParameterDef{}
+		field, def, hasDefault := strings.Cut(field, " = ")
+		typ, name, _ := strings.Cut(field, "$")
+		typ, param.IsVariadic = strings.CutSuffix(strings.TrimSpace(typ), "...")
+		param.Name, param.Type = name, strings.TrimSpace(typ)
+		if hasDefault {
+			param.HasDefault, param.Default = true, vm.signatureDefault(def)
+		}
+		signature.params = append(signature.params, param)
+	}
+	return strings.ToLower(declaration[:open]), signature
+}
+
+// splitParameters splits a parameter list at the commas outside quotes
+func splitParameters(list string) []string {
+	var fields []string
+	start, quoted := 0, false
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				fields = append(fields, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if rest := strings.TrimSpace(list[start:]); rest != "" {
+		fields = append(fields, rest)
+	}
+	return fields
+}
+
+// signatureDefault returns the value of a default in a signature: null, a
+// number, a double-quoted string or a constant
+func (vm *VM) signatureDefault(def string) *types.Value {
+	if def == "null" {
+		return types.NewNull()
+	}
+	if n, err := strconv.ParseInt(def, 10, 64); err == nil {
+		return types.NewInt(n)
+	}
+	if s, err := strconv.Unquote(def); err == nil {
+		return types.NewString(s)
+	}
+	if value, ok := vm.LookupConstant(def); ok {
+		return value
+	}
+	panic(fmt.Sprintf("unknown default %q in a signature", def))
+}
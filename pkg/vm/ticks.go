@@ -0,0 +1,86 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Ticks
+// ============================================================================
+
+// tickFunction is a callback registered by register_tick_function()
+type tickFunction struct {
+	callback *types.Value
+	args     []*types.Value
+	calling  bool // Whether the callback is running, so it does not tick itself
+}
+
+// opTicks counts a statement executed under declare(ticks=N) and calls the
+// tick functions every N statements
+// ExtendedValue: N
+func (vm *VM) opTicks(frame *Frame, instr Instruction) error {
+	vm.tickCount++
+	if vm.tickCount < int(instr.ExtendedValue) {
+		return nil
+	}
+	vm.tickCount = 0
+
+	// Tick functions may register or unregister tick functions
+	for _, fn := range append([]*tickFunction(nil), vm.tickFunctions...) {
+		if fn.calling {
+			continue
+		}
+		fn.calling = true
+		_, err := vm.CallCallable(fn.callback, fn.args)
+		fn.calling = false
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ============================================================================
+// Tick Builtins
+// ============================================================================
+
+// registerTickBuiltins registers register_tick_function() and
+// unregister_tick_function()
+func (vm *VM) registerTickBuiltins() {
+	vm.RegisterBuiltin("register_tick_function", builtinRegisterTickFunction)
+	vm.RegisterBuiltin("unregister_tick_function", builtinUnregisterTickFunction)
+}
+
+// register_tick_function(callable $callback, mixed ...$args): bool
+func builtinRegisterTickFunction(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("register_tick_function() expects at least 1 argument, 0 given")
+	}
+	if !vm.IsCallable(args[0]) {
+		return nil, vm.ThrowError("TypeError", "register_tick_function(): Argument #1 ($callback) must be a valid callback, %s given",
+			args[0].Deref().TypeName())
+	}
+	vm.tickFunctions = append(vm.tickFunctions, &tickFunction{
+		callback: args[0].Deref(),
+		args:     append([]*types.Value(nil), args[1:]...),
+	})
+	return types.NewBool(true), nil
+}
+
+// unregister_tick_function(callable $callback): void
+func builtinUnregisterTickFunction(vm *VM, args []*types.Value) (*types.Value, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("unregister_tick_function() expects exactly 1 argument, 0 given")
+	}
+	callback := args[0].Deref()
+	kept := vm.tickFunctions[:0]
+	for _, fn := range vm.tickFunctions {
+		if !fn.callback.Equals(callback) {
+			kept = append(kept, fn)
+		}
+	}
+	vm.tickFunctions = kept
+	return types.NewNull(), nil
+}
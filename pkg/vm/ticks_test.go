@@ -0,0 +1,57 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// tickingMain returns a main function of n statements compiled under
+// declare(ticks=every)
+func tickingMain(n int, every uint32) *CompiledFunction {
+	fn := &CompiledFunction{Name: "main", NumLocals: 10}
+	for i := 0; i < n; i++ {
+		fn.Instructions = append(fn.Instructions, Instruction{Opcode: OpTicks, ExtendedValue: every})
+	}
+	return fn
+}
+
+func TestTicks_CallsTickFunctions(t *testing.T) {
+	vm := New()
+	vm.RegisterBuiltin("tick", func(vm *VM, args []*types.Value) (*types.Value, error) {
+		vm.writeOutput([]byte("tick:" + args[0].ToString() + ";"))
+		return types.NewNull(), nil
+	})
+
+	result, err := builtinRegisterTickFunction(vm, []*types.Value{types.NewString("tick"), types.NewString("a")})
+	if err != nil || !result.ToBool() {
+		t.Fatalf("register_tick_function() = %v, %v", result, err)
+	}
+	if err := runMain(vm, tickingMain(5, 2)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := vm.GetOutput(); got != "tick:a;tick:a;" {
+		t.Errorf("Expected a tick every 2 statements, got %q", got)
+	}
+
+	builtinUnregisterTickFunction(vm, []*types.Value{types.NewString("tick")})
+	vm.frameIndex = -1
+	if err := runMain(vm, tickingMain(4, 1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := vm.GetOutput(); got != "tick:a;tick:a;" {
+		t.Errorf("Expected no tick after unregister_tick_function(), got %q", got)
+	}
+}
+
+func TestRegisterTickFunction_InvalidCallback(t *testing.T) {
+	vm := New()
+	_, err := builtinRegisterTickFunction(vm, []*types.Value{types.NewString("no_such_function")})
+	expectThrown(t, err, "TypeError", "register_tick_function(): Argument #1 ($callback) must be a valid callback, string given")
+}
+
+func TestScriptFunction_StrictTypes(t *testing.T) {
+	if fn := scriptFunction(&Script{Path: "strict.php", StrictTypes: true}); !fn.StrictTypes {
+		t.Error("Expected the main function of a strict_types script to be strict")
+	}
+}
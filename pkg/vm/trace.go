@@ -0,0 +1,385 @@
+package vm
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Execution Tracing
+// ============================================================================
+
+// maxTraceString is the longest string value shown in full in a trace
+const maxTraceString = 32
+
+// TraceOptions selects the instructions a Tracer logs and how
+type TraceOptions struct {
+	Functions []string // Functions or Class::methods whose instructions are logged, all if empty
+	Files     []string // Files whose instructions are logged, matched like breakpoints; all if empty
+	JSON      bool     // Write JSON Lines rather than text
+	RingSize  int      // Keep only the last RingSize entries, written by Dump, rather than writing them
+}
+
+// TraceOperand is an operand of a traced instruction with its value
+type TraceOperand struct {
+	Kind  string `json:"kind"` // CONST, TMP, VAR, CV, or JMP for jump targets
+	Index uint32 `json:"index"`
+	Name  string `json:"name,omitempty"`  // Name of a compiled variable
+	Value string `json:"value,omitempty"` // Value, in a var_dump()-like form
+}
+
+// TraceEntry is an instruction executed. An instruction that runs others
+// before it completes, like a call, is logged when they start, and its
+// result follows them in an entry marked Return.
+type TraceEntry struct {
+	Seq      uint64        `json:"seq"`
+	Depth    int           `json:"depth"`
+	File     string        `json:"file"`
+	Line     int           `json:"line"`
+	Function string        `json:"function"`
+	IP       int           `json:"ip"`
+	Opcode   string        `json:"op"`
+	Op1      *TraceOperand `json:"op1,omitempty"`
+	Op2      *TraceOperand `json:"op2,omitempty"`
+	Extended uint32        `json:"ext,omitempty"`
+	Result   *TraceOperand `json:"result,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Return   bool          `json:"return,omitempty"`
+
+	logged bool // Whether the entry was logged before it completed
+}
+
+// Tracer logs the instructions a VM executes, with the values of their
+// operands and results. The VM consults it around each instruction (see
+// dispatch).
+type Tracer struct {
+	options TraceOptions
+	out     *bufio.Writer
+	err     error // First error writing the trace
+
+	seq  uint64
+	open []*TraceEntry // Instructions started and not completed, innermost last
+
+	ring []TraceEntry // Last entries in ring-buffer mode
+	next int          // Slot of the next entry in ring
+	full bool         // Whether ring wrapped around
+}
+
+// NewTracer creates a tracer writing to w, which may be nil in ring-buffer
+// mode
+func NewTracer(w io.Writer, options TraceOptions) *Tracer {
+	t := &Tracer{options: options}
+	if options.RingSize > 0 {
+		t.ring = make([]TraceEntry, options.RingSize)
+	} else if w != nil {
+		t.out = bufio.NewWriter(w)
+	}
+	return t
+}
+
+// EnableTracing starts tracing the instructions the VM executes
+func (vm *VM) EnableTracing(w io.Writer, options TraceOptions) *Tracer {
+	vm.tracer = NewTracer(w, options)
+	return vm.tracer
+}
+
+// Tracer returns the active tracer (nil when disabled)
+func (vm *VM) Tracer() *Tracer {
+	return vm.tracer
+}
+
+// Close writes the entries buffered and returns the first error writing
+// the trace
+func (t *Tracer) Close() error {
+	if t.out != nil {
+		if err := t.out.Flush(); t.err == nil {
+			t.err = err
+		}
+	}
+	return t.err
+}
+
+// Entries returns the entries kept in ring-buffer mode, oldest first
+func (t *Tracer) Entries() []TraceEntry {
+	if !t.full {
+		return append([]TraceEntry(nil), t.ring[:t.next]...)
+	}
+	return append(append([]TraceEntry(nil), t.ring[t.next:]...), t.ring[:t.next]...)
+}
+
+// Dump writes the entries kept in ring-buffer mode, oldest first, like a
+// crash dump of the last instructions executed
+func (t *Tracer) Dump(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	for _, entry := range t.Entries() {
+		if err := t.format(out, &entry); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// matches reports whether the filters select the instructions of a frame
+func (t *Tracer) matches(vm *VM, frame *Frame) bool {
+	if len(t.options.Functions) > 0 {
+		name := frameFunction(frame)
+		found := false
+		for _, function := range t.options.Functions {
+			if strings.EqualFold(strings.TrimPrefix(function, "\\"), name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(t.options.Files) > 0 {
+		file := vm.frameFile(frame)
+		for _, name := range t.options.Files {
+			if sameFile(file, name) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// before starts the entry of an instruction about to execute, nil if the
+// filters skip it. The instructions still running are logged first, so a
+// call shows before the instructions it runs.
+func (t *Tracer) before(vm *VM, frame *Frame, instr Instruction) *TraceEntry {
+	if !t.matches(vm, frame) {
+		return nil
+	}
+	for _, entry := range t.open {
+		if !entry.logged {
+			entry.logged = true
+			t.log(entry)
+		}
+	}
+
+	t.seq++
+	entry := &TraceEntry{
+		Seq:      t.seq,
+		Depth:    vm.frameIndex,
+		File:     vm.frameFile(frame),
+		Line:     int(instr.Lineno),
+		Function: frameFunction(frame),
+		IP:       frame.ip - 1,
+		Opcode:   instr.Opcode.String(),
+		Extended: instr.ExtendedValue,
+	}
+	jump := traceJumpOperand(&instr)
+	entry.Op1 = traceOperand(vm, frame, instr.Op1, jump == &instr.Op1)
+	entry.Op2 = traceOperand(vm, frame, instr.Op2, jump == &instr.Op2)
+	t.open = append(t.open, entry)
+	return entry
+}
+
+// after completes the entry of an instruction with its result
+func (t *Tracer) after(vm *VM, frame *Frame, instr Instruction, entry *TraceEntry, err error) {
+	for i := len(t.open) - 1; i >= 0; i-- {
+		if t.open[i] == entry {
+			t.open = t.open[:i]
+			break
+		}
+	}
+	entry.Result = traceOperand(vm, frame, instr.Result, false)
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if entry.logged {
+		if entry.Result == nil && entry.Error == "" {
+			return
+		}
+		entry = &TraceEntry{
+			Seq:      entry.Seq,
+			Depth:    entry.Depth,
+			File:     entry.File,
+			Line:     entry.Line,
+			Function: entry.Function,
+			IP:       entry.IP,
+			Opcode:   entry.Opcode,
+			Result:   entry.Result,
+			Error:    entry.Error,
+			Return:   true,
+		}
+	}
+	t.log(entry)
+}
+
+// log writes an entry, or keeps it in ring-buffer mode
+func (t *Tracer) log(entry *TraceEntry) {
+	if t.ring != nil {
+		t.ring[t.next] = *entry
+		t.next++
+		if t.next == len(t.ring) {
+			t.next, t.full = 0, true
+		}
+		return
+	}
+	if t.out != nil && t.err == nil {
+		t.err = t.format(t.out, entry)
+	}
+}
+
+// format writes an entry as a JSON line or a line of text
+func (t *Tracer) format(w *bufio.Writer, entry *TraceEntry) error {
+	if t.options.JSON {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+	_, err := fmt.Fprintln(w, entry)
+	return err
+}
+
+// String formats an entry as a line of text: its number, position and
+// function, then the instruction with its operands and result
+func (e *TraceEntry) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#%d %s%s:%d %s [%d] %s", e.Seq, strings.Repeat("  ", e.Depth), e.File, e.Line, e.Function, e.IP, e.Opcode)
+	if e.Return {
+		sb.WriteString(" returned")
+	}
+	for i, op := range []*TraceOperand{e.Op1, e.Op2} {
+		if op == nil {
+			continue
+		}
+		if i > 0 && e.Op1 != nil {
+			sb.WriteString(",")
+		}
+		sb.WriteString(" " + op.String())
+	}
+	if e.Extended != 0 {
+		fmt.Fprintf(&sb, " [ext=%d]", e.Extended)
+	}
+	if e.Result != nil {
+		sb.WriteString(" => " + e.Result.String())
+	}
+	if e.Error != "" {
+		sb.WriteString(" !! " + e.Error)
+	}
+	return sb.String()
+}
+
+// String formats an operand as its kind and index, with the variable name
+// and value when known
+func (op *TraceOperand) String() string {
+	if op.Kind == "JMP" {
+		return fmt.Sprintf("->%d", op.Index)
+	}
+	s := op.Kind + strconv.FormatUint(uint64(op.Index), 10)
+	if op.Name != "" {
+		s += "($" + op.Name + ")"
+	}
+	if op.Value != "" {
+		s += "=" + op.Value
+	}
+	return s
+}
+
+// traceOperand describes an operand with its current value, nil for
+// unused operands
+func traceOperand(vm *VM, frame *Frame, op Operand, jump bool) *TraceOperand {
+	if op.Type == OpUnused {
+		return nil
+	}
+	if jump {
+		return &TraceOperand{Kind: "JMP", Index: op.Value}
+	}
+	operand := &TraceOperand{Index: op.Value}
+	switch op.Type {
+	case OpConst:
+		operand.Kind = "CONST"
+		if constant, ok := vm.constantOperand(frame, op); ok {
+			switch decl := constant.(type) {
+			case *FunctionDecl:
+				operand.Value = "function " + decl.Function.Name
+				return operand
+			case *ClassDecl:
+				operand.Value = "class " + decl.Class.Name
+				return operand
+			}
+		}
+	case OpTmpVar:
+		operand.Kind = "TMP"
+	case OpVar, OpCV:
+		operand.Kind = "CV"
+		if op.Type == OpVar {
+			operand.Kind = "VAR"
+		}
+		if int(op.Value) < len(frame.fn.Variables) {
+			operand.Name = frame.fn.Variables[op.Value]
+		}
+	}
+	if value, err := vm.getOperandValue(frame, op); err == nil {
+		operand.Value = describeTraceValue(value)
+	}
+	return operand
+}
+
+// traceJumpOperand returns the operand holding the target of a jump
+// opcode, nil for the other opcodes
+func traceJumpOperand(instr *Instruction) *Operand {
+	var target *Operand
+	switch instr.Opcode {
+	case OpJmp, OpFastCall:
+		target = &instr.Op1
+	case OpJmpZ, OpJmpNZ, OpJmpZEx, OpJmpNZEx, OpJmpSet, OpCoalesce, OpJmpNull,
+		OpCatch, OpBindInitStaticOrJmp, OpFeFetchR, OpFeFetchRW, OpNew:
+		target = &instr.Op2
+	default:
+		return nil
+	}
+	if target.Type != OpConst {
+		return nil
+	}
+	return target
+}
+
+// describeTraceValue renders a value like var_dump() does on one line,
+// with long strings truncated and arrays and objects summarized
+func describeTraceValue(value *types.Value) string {
+	if value == nil {
+		return "NULL"
+	}
+	value = value.Deref()
+	switch value.Type() {
+	case types.TypeUndef:
+		return "UNDEF"
+	case types.TypeNull:
+		return "NULL"
+	case types.TypeBool:
+		return fmt.Sprintf("bool(%t)", value.ToBool())
+	case types.TypeInt:
+		return fmt.Sprintf("int(%d)", value.ToInt())
+	case types.TypeFloat:
+		return "float(" + types.FormatFloat(value.ToFloat(), types.SerializePrecision()) + ")"
+	case types.TypeString:
+		s := value.ToString()
+		quoted := strconv.Quote(s)
+		if len(s) > maxTraceString {
+			quoted = strconv.Quote(s[:maxTraceString]) + "..."
+		}
+		return fmt.Sprintf("string(%d) %s", len(s), quoted)
+	case types.TypeArray:
+		return fmt.Sprintf("array(%d)", value.ToArray().Len())
+	case types.TypeObject:
+		object := value.ToObject()
+		return fmt.Sprintf("object(%s)#%d", object.ClassName, object.ObjectID)
+	default:
+		return value.TypeString()
+	}
+}
@@ -0,0 +1,114 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// traceProgram runs echo double(4); on line 2 of main.php and jumps over
+// line 3 to echo "done"; on line 4, with double() in double.php, under a
+// tracer
+func traceProgram(t *testing.T, w *bytes.Buffer, options TraceOptions) *Tracer {
+	t.Helper()
+	vm := New()
+	vm.SetScriptPath("main.php")
+	vm.constants = []interface{}{"double", int64(4), "done"}
+	double := doubleFunction("double")
+	double.Variables = []string{"x"}
+	double.File = "lib/double.php"
+	double.Instructions[0].Lineno = 10
+	double.Instructions[1].Lineno = 11
+	vm.RegisterFunction("double", double)
+
+	tracer := vm.EnableTracing(w, options)
+	err := vm.Execute(Instructions{
+		{Opcode: OpInitFcall, Op2: Operand{Type: OpConst, Value: 0}, Lineno: 2},
+		{Opcode: OpSendVal, Op1: Operand{Type: OpConst, Value: 1}, Lineno: 2},
+		{Opcode: OpDoFcall, Result: Operand{Type: OpTmpVar, Value: 0}, Lineno: 2},
+		{Opcode: OpEcho, Op1: Operand{Type: OpTmpVar, Value: 0}, Lineno: 2},
+		{Opcode: OpJmp, Op1: Operand{Type: OpConst, Value: 6}, Lineno: 3},
+		{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 1}, Lineno: 3},
+		{Opcode: OpEcho, Op1: Operand{Type: OpConst, Value: 2}, Lineno: 4},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tracer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return tracer
+}
+
+func TestTracer_Text(t *testing.T) {
+	var out bytes.Buffer
+	traceProgram(t, &out, TraceOptions{})
+	expected := `#1 main.php:2 main [0] INIT_FCALL CONST0=string(6) "double"
+#2 main.php:2 main [1] SEND_VAL CONST1=int(4)
+#3 main.php:2 main [2] DO_FCALL
+#4   lib/double.php:10 double [0] ADD CV0($x)=int(4), CV0($x)=int(4) => TMP0=int(8)
+#5   lib/double.php:11 double [1] RETURN TMP0=int(8)
+#3 main.php:2 main [2] DO_FCALL returned => TMP0=int(8)
+#6 main.php:2 main [3] ECHO TMP0=int(8)
+#7 main.php:3 main [4] JMP ->6
+#8 main.php:4 main [6] ECHO CONST2=string(4) "done"
+`
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestTracer_JSON(t *testing.T) {
+	var out bytes.Buffer
+	traceProgram(t, &out, TraceOptions{JSON: true})
+	var entries []TraceEntry
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var entry TraceEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	echo := entries[6]
+	if echo.Opcode != "ECHO" || echo.Op1 == nil || echo.Op1.Kind != "TMP" || echo.Op1.Value != "int(8)" {
+		t.Errorf("Unexpected ECHO entry %+v", echo)
+	}
+	if call := entries[5]; !call.Return || call.Result == nil || call.Result.Value != "int(8)" {
+		t.Errorf("Expected the result of DO_FCALL after the call, got %+v", call)
+	}
+}
+
+func TestTracer_Filters(t *testing.T) {
+	var out bytes.Buffer
+	traceProgram(t, &out, TraceOptions{Functions: []string{"DOUBLE"}})
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[0], "double.php:10") {
+		t.Errorf("Expected the 2 instructions of double(), got:\n%s", out.String())
+	}
+
+	out.Reset()
+	traceProgram(t, &out, TraceOptions{Files: []string{"main.php"}})
+	if strings.Contains(out.String(), "double.php") || !strings.Contains(out.String(), "DO_FCALL => TMP0=int(8)") {
+		t.Errorf("Expected the instructions of main.php only, got:\n%s", out.String())
+	}
+}
+
+func TestTracer_Ring(t *testing.T) {
+	var out bytes.Buffer
+	tracer := traceProgram(t, &out, TraceOptions{RingSize: 3})
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing written in ring-buffer mode, got:\n%s", out.String())
+	}
+	entries := tracer.Entries()
+	if len(entries) != 3 || entries[0].Seq != 6 || entries[2].Opcode != "ECHO" {
+		t.Fatalf("Expected the last 3 entries, got %+v", entries)
+	}
+
+	var dump bytes.Buffer
+	if err := tracer.Dump(&dump); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(dump.String(), "#6 ") || strings.Count(dump.String(), "\n") != 3 {
+		t.Errorf("Unexpected dump:\n%s", dump.String())
+	}
+}
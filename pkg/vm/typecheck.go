@@ -0,0 +1,382 @@
+package vm
+
+import (
+	"math"
+	"strings"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Type Declarations
+// ============================================================================
+
+// typeScope resolves the relative class types of a declaration: self and
+// parent name the declaring class and its parent, static the called class
+type typeScope struct {
+	self   *types.ClassEntry
+	static *types.ClassEntry
+}
+
+// frameTypeScope returns the scope of the declarations of the function a
+// frame executes
+func frameTypeScope(frame *Frame) typeScope {
+	return typeScope{self: frame.currentClass, static: frame.calledClass}
+}
+
+// coerceType checks a value against a type declaration. It returns the
+// value to use, converted when PHP's juggling rules allow it, and whether
+// the value is accepted. An int is widened for a float type in both modes;
+// in coercive mode (strict false) scalars are converted to the first of
+// int, float, string and bool the declaration accepts.
+func (vm *VM) coerceType(decl string, value *types.Value, strict bool, scope typeScope) (*types.Value, bool, error) {
+	info := types.ParseType(decl)
+	value = value.Deref()
+
+	for _, alternative := range info.Alternatives {
+		if vm.intersectionAccepts(alternative, value, scope) {
+			return value, true, nil
+		}
+	}
+	if value.IsInt() && info.Accepts("float") {
+		return types.NewFloat(float64(value.ToInt())), true, nil
+	}
+	if strict {
+		return value, false, nil
+	}
+	return vm.coerceScalar(info, value)
+}
+
+// intersectionAccepts reports whether a value is of all the types of an
+// alternative of a declaration
+func (vm *VM) intersectionAccepts(alternative []string, value *types.Value, scope typeScope) bool {
+	for _, member := range alternative {
+		if !vm.typeAccepts(member, value, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// typeAccepts reports whether a value is of a single type as it is
+func (vm *VM) typeAccepts(member string, value *types.Value, scope typeScope) bool {
+	switch member {
+	case "mixed":
+		return true
+	case "null", "void":
+		return value.IsNull()
+	case "int":
+		return value.IsInt()
+	case "float":
+		return value.IsFloat()
+	case "string":
+		return value.IsString()
+	case "bool":
+		return value.IsBool()
+	case "false":
+		return value.IsBool() && !value.ToBool()
+	case "true":
+		return value.IsBool() && value.ToBool()
+	case "array":
+		return value.IsArray()
+	case "object":
+		return value.IsObject()
+	case "callable":
+		return vm.IsCallable(value)
+	case "never":
+		return false
+	}
+
+	if !value.IsObject() {
+		return false
+	}
+	class := value.ToObject().ClassEntry
+	switch member {
+	case "self":
+		return scope.self != nil && vm.isInstanceOf(class, scope.self.Name)
+	case "parent":
+		return scope.self != nil && scope.self.ParentClass != nil && vm.isInstanceOf(class, scope.self.ParentClass.Name)
+	case "static":
+		return scope.static != nil && vm.isInstanceOf(class, scope.static.Name)
+	}
+	return vm.isInstanceOf(class, member)
+}
+
+// coerceScalar converts a value for a declaration it does not match in
+// coercive mode. Only scalars and Stringable objects (for string) are
+// converted; null never is.
+func (vm *VM) coerceScalar(info *types.TypeInfo, value *types.Value) (*types.Value, bool, error) {
+	if value.IsObject() {
+		if !info.Accepts("string") {
+			return value, false, nil
+		}
+		s, ok, err := vm.stringable(value)
+		if err != nil || !ok {
+			return value, false, err
+		}
+		return types.NewString(s), true, nil
+	}
+	if !value.IsScalar() || value.IsNull() {
+		return value, false, nil
+	}
+
+	toInt, toFloat := info.Accepts("int"), info.Accepts("float")
+	if value.IsString() && (toInt || toFloat) {
+		if number, ok := vm.numericString(value.ToString()); ok {
+			value = number
+		} else {
+			toInt, toFloat = false, false
+		}
+	}
+	if toInt {
+		if result, ok := vm.coerceInt(value, toFloat || info.Accepts("string")); ok {
+			return result, true, nil
+		}
+	}
+	if toFloat && !value.IsString() {
+		return types.NewFloat(value.ToFloat()), true, nil
+	}
+	if info.Accepts("string") && !value.IsString() {
+		return types.NewString(value.ToString()), true, nil
+	}
+	if info.Accepts("bool") {
+		return types.NewBool(value.ToBool()), true, nil
+	}
+	return value, false, nil
+}
+
+// coerceInt converts a bool or float to int. A float with a fractional
+// part is truncated with a deprecation, unless another member of the
+// declaration (float or string) takes it as it is.
+func (vm *VM) coerceInt(value *types.Value, keepFraction bool) (*types.Value, bool) {
+	if value.IsInt() || value.IsBool() {
+		return types.NewInt(value.ToInt()), true
+	}
+	if !value.IsFloat() {
+		return nil, false
+	}
+
+	f := value.ToFloat()
+	if math.IsNaN(f) || math.IsInf(f, 0) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return nil, false
+	}
+	if f != math.Trunc(f) {
+		if keepFraction {
+			return nil, false
+		}
+		vm.deprecated("Implicit conversion from float %s to int loses precision", value.ToString())
+	}
+	return types.NewInt(int64(f)), true
+}
+
+// numericString parses a string passed for a number type: leading-numeric
+// strings are accepted with a warning, others are rejected
+func (vm *VM) numericString(s string) (*types.Value, bool) {
+	number, kind := types.ParseNumeric(s)
+	switch kind {
+	case types.NotNumeric:
+		return nil, false
+	case types.LeadingNumeric:
+		vm.warning("A non-numeric value encountered")
+	}
+	return number, true
+}
+
+// ============================================================================
+// Parameters and Return Values
+// ============================================================================
+
+// opRecv receives a required parameter
+// Op1: parameter index, Result: the parameter's compiled variable
+func (vm *VM) opRecv(frame *Frame, instr Instruction) error {
+	index := int(instr.Op1.Value)
+	if index >= len(frame.args) || frame.args[index] == nil {
+		return vm.tooFewArguments(frame)
+	}
+	return vm.receiveArgument(frame, index, frame.args[index], instr.Result)
+}
+
+// opRecvInit receives a parameter with a default value
+// Op1: parameter index, Op2: default value, Result: compiled variable
+func (vm *VM) opRecvInit(frame *Frame, instr Instruction) error {
+	index := int(instr.Op1.Value)
+	if index < len(frame.args) && frame.args[index] != nil {
+		return vm.receiveArgument(frame, index, frame.args[index], instr.Result)
+	}
+	value, err := vm.getOperandValue(frame, instr.Op2)
+	if err != nil {
+		return err
+	}
+	frame.setLocal(int(instr.Result.Value), assignValue(value))
+	return nil
+}
+
+// opRecvVariadic collects the remaining arguments into an array, followed
+// by the named arguments matching no parameter under their names
+// Op1: index of the variadic parameter, Result: compiled variable
+func (vm *VM) opRecvVariadic(frame *Frame, instr Instruction) error {
+	index := int(instr.Op1.Value)
+	rest := types.NewEmptyArray()
+	for i := index; i < len(frame.args); i++ {
+		value, err := vm.checkArgument(frame, i, frame.args[i])
+		if err != nil {
+			return err
+		}
+		rest.Append(value)
+	}
+	for _, arg := range frame.namedRest {
+		value, err := vm.checkArgument(frame, index, arg.value)
+		if err != nil {
+			return err
+		}
+		rest.Set(types.NewString(arg.name), value)
+	}
+	frame.setLocal(int(instr.Result.Value), types.NewArray(rest))
+	return nil
+}
+
+// receiveArgument checks an argument against its parameter's type and
+// stores it in the parameter's compiled variable
+func (vm *VM) receiveArgument(frame *Frame, index int, arg *types.Value, result Operand) error {
+	value, err := vm.checkArgument(frame, index, arg)
+	if err != nil {
+		return err
+	}
+	param := frameParameter(frame, index)
+	byRef := param != nil && param.PassedByRef
+	if arg.IsReference() {
+		if !byRef {
+			value = assignValue(value.Deref())
+		} else if value != arg {
+			arg.Assign(value) // a coerced argument is written back
+			value = arg
+		}
+	}
+	frame.setLocal(int(result.Value), value)
+	return nil
+}
+
+// checkArgument checks an argument against the declared type of its
+// parameter, using the caller's typing mode. Arguments to a variadic
+// parameter are checked against its type.
+func (vm *VM) checkArgument(frame *Frame, index int, arg *types.Value) (*types.Value, error) {
+	param := frameParameter(frame, index)
+	if param == nil || param.Type == "" {
+		return arg, nil
+	}
+	if arg.Deref().IsNull() && param.HasDefault && param.Default != nil && param.Default.IsNull() {
+		return arg, nil // T $x = null is implicitly nullable
+	}
+
+	value, ok, err := vm.coerceType(param.Type, arg, frame.strictArgs, frameTypeScope(frame))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, vm.ThrowError("TypeError", "%s(): Argument #%d ($%s) must be of type %s, %s given",
+			frame.functionName(), index+1, param.Name, param.Type, arg.TypeName())
+	}
+	if value == arg.Deref() {
+		return arg, nil
+	}
+	return value, nil
+}
+
+// frameParameter returns the definition of the parameter receiving an
+// argument, the variadic one for extra arguments; nil if unknown
+func frameParameter(frame *Frame, index int) *types.ParameterDef {
+	params := frame.fn.Parameters
+	if index < len(params) {
+		return params[index]
+	}
+	if len(params) > 0 && params[len(params)-1].IsVariadic {
+		return params[len(params)-1]
+	}
+	return nil
+}
+
+// tooFewArguments throws the ArgumentCountError for a call that did not
+// pass a required parameter
+func (vm *VM) tooFewArguments(frame *Frame) error {
+	required, bound := 0, "exactly"
+	for _, param := range frame.fn.Parameters {
+		if param.HasDefault || param.IsVariadic {
+			bound = "at least"
+			continue
+		}
+		required++
+	}
+	return vm.ThrowError("ArgumentCountError", "Too few arguments to function %s(), %d passed and %s %d expected",
+		frame.functionName(), len(frame.args), bound, required)
+}
+
+// opVerifyReturnType checks a returned value against the declared return
+// type, using the typing mode of the function's own file
+// Op1: returned value (unused for a return without one), Result: where
+// the checked value is stored
+func (vm *VM) opVerifyReturnType(frame *Frame, instr Instruction) error {
+	decl := frame.fn.ReturnType
+	if decl == "" || strings.EqualFold(decl, "void") {
+		return nil
+	}
+	if strings.EqualFold(decl, "never") {
+		return vm.ThrowError("TypeError", "%s(): never-returning function must not implicitly return", frame.functionName())
+	}
+	if instr.Op1.Type == OpUnused {
+		return vm.ThrowError("TypeError", "%s(): Return value must be of type %s, none returned", frame.functionName(), decl)
+	}
+
+	returned, err := vm.getOperandValue(frame, instr.Op1)
+	if err != nil {
+		return err
+	}
+	value, ok, err := vm.coerceType(decl, returned, frame.fn.StrictTypes, frameTypeScope(frame))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return vm.ThrowError("TypeError", "%s(): Return value must be of type %s, %s returned",
+			frame.functionName(), decl, returned.TypeName())
+	}
+	if value == returned.Deref() {
+		value = returned
+	}
+	return vm.setOperandValue(frame, instr.Result, value)
+}
+
+// ============================================================================
+// Typed Properties
+// ============================================================================
+
+// checkPropertyType checks a value assigned to a typed property, using the
+// typing mode of the code doing the write. A reference is only checked,
+// never converted.
+func (vm *VM) checkPropertyType(frame *Frame, obj *types.Object, name string, value *types.Value) (*types.Value, error) {
+	prop, ok := obj.Properties[obj.PropertyKey(name, frame.currentClass)]
+	if !ok || prop.Type == "" {
+		return value, nil
+	}
+
+	scope := typeScope{self: obj.ClassEntry, static: obj.ClassEntry}
+	declaring := obj.ClassName
+	if prop.DeclaringClass != "" {
+		declaring = prop.DeclaringClass
+		if class, found := vm.lookupClass(prop.DeclaringClass); found {
+			scope.self = class
+		}
+	}
+
+	strict := frame.fn.StrictTypes || value.IsReference()
+	checked, accepted, err := vm.coerceType(prop.Type, value, strict, scope)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, vm.ThrowError("TypeError", "Cannot assign %s to property %s::$%s of type %s",
+			value.TypeName(), declaring, name, prop.Type)
+	}
+	if checked == value.Deref() {
+		return value, nil
+	}
+	return checked, nil
+}
@@ -0,0 +1,305 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/krizos/php-go/pkg/types"
+)
+
+// ============================================================================
+// Type Declaration Test Helpers
+// ============================================================================
+
+// typedFunction returns function name(<paramType> $x): <returnType> { return $x; }
+func typedFunction(name, paramType, returnType string) *CompiledFunction {
+	returned := Operand{Type: OpCV, Value: 0}
+	instructions := Instructions{
+		{Opcode: OpRecv, Op1: Operand{Type: OpConst, Value: 0}, Result: returned},
+	}
+	if returnType != "" {
+		instructions = append(instructions, Instruction{Opcode: OpVerifyReturnType, Op1: returned, Result: Operand{Type: OpTmpVar, Value: 0}})
+		returned = Operand{Type: OpTmpVar, Value: 0}
+	}
+	return &CompiledFunction{
+		Name:         name,
+		Instructions: append(instructions, Instruction{Opcode: OpReturn, Op1: returned}),
+		NumLocals:    10,
+		NumParams:    1,
+		Parameters:   []*types.ParameterDef{{Name: "x", Type: paramType}},
+		ReturnType:   returnType,
+	}
+}
+
+// expectThrown checks that err is a thrown class with the given message
+func expectThrown(t *testing.T, err error, class, message string) {
+	t.Helper()
+	thrown, ok := err.(*ThrowableError)
+	if !ok || thrown.Object.ClassEntry.Name != class || throwableProperty(thrown.Object, "message").ToString() != message {
+		t.Errorf("Expected %s %q, got %v", class, message, err)
+	}
+}
+
+// ============================================================================
+// Parameter Type Tests
+// ============================================================================
+
+func TestRecv_CoerciveMode(t *testing.T) {
+	tests := []struct {
+		decl     string
+		arg      *types.Value
+		expected *types.Value
+	}{
+		{"int", types.NewString("42"), types.NewInt(42)},
+		{"int", types.NewFloat(3.0), types.NewInt(3)},
+		{"int", types.NewBool(true), types.NewInt(1)},
+		{"float", types.NewInt(2), types.NewFloat(2)},
+		{"float", types.NewString("1.5"), types.NewFloat(1.5)},
+		{"string", types.NewInt(7), types.NewString("7")},
+		{"bool", types.NewString("abc"), types.NewBool(true)},
+		{"?int", types.NewNull(), types.NewNull()},
+		{"int|float", types.NewString("1.5"), types.NewFloat(1.5)},
+		{"int|string", types.NewFloat(1.5), types.NewString("1.5")},
+		{"mixed", types.NewString("x"), types.NewString("x")},
+	}
+
+	for _, tt := range tests {
+		vm := New()
+		vm.RegisterFunction("f", typedFunction("f", tt.decl, ""))
+		result, err := vm.CallCallable(types.NewString("f"), []*types.Value{tt.arg})
+		if err != nil {
+			t.Errorf("%s with %s: unexpected error: %v", tt.decl, tt.arg.TypeName(), err)
+			continue
+		}
+		if result.Type() != tt.expected.Type() || result.ToString() != tt.expected.ToString() {
+			t.Errorf("%s with %s: expected %s %q, got %s %q", tt.decl, tt.arg.TypeName(),
+				tt.expected.TypeName(), tt.expected.ToString(), result.TypeName(), result.ToString())
+		}
+	}
+}
+
+func TestRecv_TypeErrors(t *testing.T) {
+	tests := []struct {
+		decl string
+		arg  *types.Value
+	}{
+		{"int", types.NewString("abc")},
+		{"int", types.NewNull()},
+		{"int", types.NewArray(types.NewEmptyArray())},
+		{"array", types.NewInt(1)},
+		{"Countable", types.NewInt(1)},
+	}
+
+	for _, tt := range tests {
+		vm := New()
+		vm.RegisterFunction("f", typedFunction("f", tt.decl, ""))
+		_, err := vm.CallCallable(types.NewString("f"), []*types.Value{tt.arg})
+		expectThrown(t, err, "TypeError", "f(): Argument #1 ($x) must be of type "+tt.decl+", "+tt.arg.TypeName()+" given")
+	}
+}
+
+func TestRecv_StrictMode(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"f", "42", int64(2)}
+	vm.RegisterFunction("f", typedFunction("f", "float", ""))
+
+	// declare(strict_types=1); f(<constant>);
+	caller := func(arg uint32) *CompiledFunction {
+		return &CompiledFunction{
+			Name: "main",
+			Instructions: Instructions{
+				{Opcode: OpInitFcall, Op2: Operand{Type: OpConst, Value: 0}},
+				{Opcode: OpSendVal, Op1: Operand{Type: OpConst, Value: arg}},
+				{Opcode: OpDoFcall, Result: Operand{Type: OpCV, Value: 0}},
+			},
+			NumLocals:   10,
+			StrictTypes: true,
+		}
+	}
+
+	err := runMain(vm, caller(1))
+	expectThrown(t, err, "TypeError", "f(): Argument #1 ($x) must be of type float, string given")
+
+	// int to float widening is allowed in strict mode
+	vm.frameIndex = -1
+	main := caller(2)
+	if err := runMain(vm, main); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result := vm.currentFrame().getLocal(0); !result.IsFloat() || result.ToFloat() != 2 {
+		t.Errorf("Expected float(2), got %s %q", result.TypeName(), result.ToString())
+	}
+}
+
+func TestRecv_ClassTypes(t *testing.T) {
+	vm := New()
+	base := types.NewClassEntry("Base")
+	child := types.NewClassEntry("Child")
+	child.ParentClass = base
+	vm.classes["Base"] = base
+	vm.classes["Child"] = child
+	vm.RegisterFunction("f", typedFunction("f", "Base", ""))
+
+	obj := types.NewObject(types.NewObjectFromClass(child))
+	if _, err := vm.CallCallable(types.NewString("f"), []*types.Value{obj}); err != nil {
+		t.Errorf("subclass instance should be accepted: %v", err)
+	}
+
+	other := types.NewObject(types.NewObjectFromClass(types.NewClassEntry("Other")))
+	_, err := vm.CallCallable(types.NewString("f"), []*types.Value{other})
+	expectThrown(t, err, "TypeError", "f(): Argument #1 ($x) must be of type Base, Other given")
+}
+
+func TestRecv_IntersectionAndDNFTypes(t *testing.T) {
+	vm := New()
+	both := types.NewClassEntry("Both")
+	both.Interfaces = []*types.InterfaceEntry{types.NewInterfaceEntry("A"), types.NewInterfaceEntry("B")}
+	onlyA := types.NewClassEntry("OnlyA")
+	onlyA.Interfaces = []*types.InterfaceEntry{types.NewInterfaceEntry("A")}
+	vm.RegisterFunction("f", typedFunction("f", "A&B", ""))
+	vm.RegisterFunction("g", typedFunction("g", "(A&B)|int", ""))
+
+	obj := types.NewObject(types.NewObjectFromClass(both))
+	if _, err := vm.CallCallable(types.NewString("f"), []*types.Value{obj}); err != nil {
+		t.Errorf("A&B should accept Both: %v", err)
+	}
+	partial := types.NewObject(types.NewObjectFromClass(onlyA))
+	_, err := vm.CallCallable(types.NewString("f"), []*types.Value{partial})
+	expectThrown(t, err, "TypeError", "f(): Argument #1 ($x) must be of type A&B, OnlyA given")
+
+	result, err := vm.CallCallable(types.NewString("g"), []*types.Value{types.NewString("3")})
+	if err != nil || !result.IsInt() || result.ToInt() != 3 {
+		t.Errorf("(A&B)|int should coerce \"3\" to int(3), got %v, %v", result, err)
+	}
+	_, err = vm.CallCallable(types.NewString("g"), []*types.Value{partial})
+	expectThrown(t, err, "TypeError", "g(): Argument #1 ($x) must be of type (A&B)|int, OnlyA given")
+}
+
+func TestRecv_TooFewArguments(t *testing.T) {
+	vm := New()
+	vm.RegisterFunction("f", typedFunction("f", "int", ""))
+
+	_, err := vm.CallCallable(types.NewString("f"), nil)
+	expectThrown(t, err, "ArgumentCountError", "Too few arguments to function f(), 0 passed and exactly 1 expected")
+}
+
+func TestRecvInitAndVariadic(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{int64(10)}
+
+	// function f(int $a = 10, int ...$rest) { return [$a, $rest]; }
+	vm.RegisterFunction("f", &CompiledFunction{
+		Name: "f",
+		Instructions: Instructions{
+			{Opcode: OpRecvInit, Op1: Operand{Type: OpConst, Value: 0}, Op2: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpCV, Value: 0}},
+			{Opcode: OpRecvVariadic, Op1: Operand{Type: OpConst, Value: 1}, Result: Operand{Type: OpCV, Value: 1}},
+			{Opcode: OpInitArray, Result: Operand{Type: OpTmpVar, Value: 0}},
+			{Opcode: OpAddArrayElement, Op1: Operand{Type: OpCV, Value: 0}, Result: Operand{Type: OpTmpVar, Value: 0}},
+			{Opcode: OpAddArrayElement, Op1: Operand{Type: OpCV, Value: 1}, Result: Operand{Type: OpTmpVar, Value: 0}},
+			{Opcode: OpReturn, Op1: Operand{Type: OpTmpVar, Value: 0}},
+		},
+		NumLocals: 10,
+		NumParams: 2,
+		Parameters: []*types.ParameterDef{
+			{Name: "a", Type: "int", HasDefault: true, Default: types.NewInt(10)},
+			{Name: "rest", Type: "int", IsVariadic: true},
+		},
+	})
+
+	result, err := vm.CallCallable(types.NewString("f"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a, _ := result.ToArray().Get(types.NewInt(0))
+	if a.ToInt() != 10 {
+		t.Errorf("Expected default 10, got %v", a)
+	}
+
+	result, err = vm.CallCallable(types.NewString("f"), []*types.Value{types.NewInt(1), types.NewString("2"), types.NewInt(3)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rest, _ := result.ToArray().Get(types.NewInt(1))
+	second, _ := rest.ToArray().Get(types.NewInt(0))
+	if rest.ToArray().Len() != 2 || !second.IsInt() || second.ToInt() != 2 {
+		t.Errorf("Expected variadic [2, 3], got %v", rest)
+	}
+
+	_, err = vm.CallCallable(types.NewString("f"), []*types.Value{types.NewInt(1), types.NewInt(2), types.NewString("x")})
+	expectThrown(t, err, "TypeError", "f(): Argument #3 ($rest) must be of type int, string given")
+}
+
+// ============================================================================
+// Return Type Tests
+// ============================================================================
+
+func TestVerifyReturnType(t *testing.T) {
+	vm := New()
+	vm.RegisterFunction("f", typedFunction("f", "", "int"))
+
+	result, err := vm.CallCallable(types.NewString("f"), []*types.Value{types.NewString("5")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsInt() || result.ToInt() != 5 {
+		t.Errorf("Expected int(5), got %s %q", result.TypeName(), result.ToString())
+	}
+
+	_, err = vm.CallCallable(types.NewString("f"), []*types.Value{types.NewString("five")})
+	expectThrown(t, err, "TypeError", "f(): Return value must be of type int, string returned")
+
+	strict := typedFunction("g", "", "int")
+	strict.StrictTypes = true
+	vm.RegisterFunction("g", strict)
+	_, err = vm.CallCallable(types.NewString("g"), []*types.Value{types.NewString("5")})
+	expectThrown(t, err, "TypeError", "g(): Return value must be of type int, string returned")
+}
+
+func TestVerifyReturnType_NoneReturned(t *testing.T) {
+	vm := New()
+	for name, decl := range map[string]string{"f": "?int", "g": "never"} {
+		vm.RegisterFunction(name, &CompiledFunction{
+			Name: name,
+			Instructions: Instructions{
+				{Opcode: OpVerifyReturnType},
+				{Opcode: OpReturn},
+			},
+			NumLocals:  10,
+			ReturnType: decl,
+		})
+	}
+
+	_, err := vm.CallCallable(types.NewString("f"), nil)
+	expectThrown(t, err, "TypeError", "f(): Return value must be of type ?int, none returned")
+	_, err = vm.CallCallable(types.NewString("g"), nil)
+	expectThrown(t, err, "TypeError", "g(): never-returning function must not implicitly return")
+}
+
+// ============================================================================
+// Typed Property Tests
+// ============================================================================
+
+func TestAssignProperty_Typed(t *testing.T) {
+	vm := New()
+	class := types.NewClassEntry("Point")
+	class.Properties["x"] = &types.PropertyDef{Name: "x", Type: "int", Visibility: types.VisibilityPublic}
+	vm.classes["Point"] = class
+	obj := types.NewObjectFromClass(class)
+	frame := NewFrame(&CompiledFunction{Name: "main", NumLocals: 10})
+
+	if err := vm.assignProperty(frame, obj, "x", types.NewString("12")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if x := obj.Properties["x"].Value; !x.IsInt() || x.ToInt() != 12 {
+		t.Errorf("Expected int(12), got %s %q", x.TypeName(), x.ToString())
+	}
+
+	err := vm.assignProperty(frame, obj, "x", types.NewString("twelve"))
+	expectThrown(t, err, "TypeError", "Cannot assign string to property Point::$x of type int")
+
+	frame.fn.StrictTypes = true
+	err = vm.assignProperty(frame, obj, "x", types.NewString("12"))
+	expectThrown(t, err, "TypeError", "Cannot assign string to property Point::$x of type int")
+	if x := obj.Properties["x"].Value; x.ToInt() != 12 {
+		t.Errorf("Rejected value should not be assigned, got %v", x)
+	}
+}
@@ -1,8 +1,15 @@
 package vm
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
+	"github.com/krizos/php-go/pkg/runtime"
+	"github.com/krizos/php-go/pkg/stdlib/header"
+	"github.com/krizos/php-go/pkg/stdlib/session"
 	"github.com/krizos/php-go/pkg/types"
 )
 
@@ -17,6 +24,16 @@ type VM struct {
 	// Function registry (user functions and built-ins)
 	functions map[string]*CompiledFunction
 
+	// Go-implemented builtin functions (keyed by lowercase name)
+	builtins map[string]BuiltinFunction
+
+	// Declared parameters of Go functions registered with DeclareBuiltin
+	// (keyed by lowercase name)
+	signatures map[string]*builtinSignature
+
+	// Go extensions loaded into the VM, in load order
+	extensions []*Extension
+
 	// Class registry
 	classes map[string]*CompiledClass
 
@@ -28,24 +45,151 @@ type VM struct {
 	// Output buffer
 	output []byte
 
-	// Maximum stack depth (default 1000)
+	// Maximum stack depth (xdebug.max_nesting_level, default 1000)
 	maxStackDepth int
+
+	// Opcode profiler (nil unless --profile-opcodes is enabled)
+	profiler *OpcodeProfiler
+
+	// Function profiler (nil unless --profile is enabled)
+	callProfiler *CallProfiler
+
+	// Debugger stopping the execution (nil unless one is attached)
+	debugger *Debugger
+
+	// Instruction tracer (nil unless --trace is enabled)
+	tracer *Tracer
+
+	// Code coverage (nil unless started) and the scripts loaded, by path
+	coverage      *CodeCoverage
+	loadedScripts map[string]*CompiledFunction
+
+	// Path of the executing script (reported in exceptions)
+	scriptPath string
+
+	// include/require state
+	compileScript ScriptCompiler           // Compiles included files (set by the embedder)
+	includePath   []string                 // Directories searched for relative includes
+	includedFiles map[string]bool          // Real paths of loaded files (for *_once)
+	includedOrder []string                 // Loaded files in load order
+	scriptCache   *scriptCache             // Opcode cache keyed by real path
+	mounts        []mount                  // File systems served ahead of the disk (see Mount)
+
+	// Class autoloading
+	autoloaders        []*types.Value      // Autoload queue (PHP callables, Go loaders wrapped as Closures)
+	autoloading        map[string]bool     // Classes whose autoload is in progress (lowercased)
+	autoloadExtensions []string            // File extensions tried by spl_autoload()
+	psr4Prefixes       map[string][]string // PSR-4 namespace prefix => base directories
+	classMap           map[string]string   // Lowercase class name => file declaring it (see AddClassMap)
+
+	// Static variables of functions and methods, by function then name.
+	// Closures keep their own (Closure.StaticVars).
+	staticVars map[*CompiledFunction]map[string]*types.Value
+
+	// Error reporting (see errors.go)
+	errorReporting runtime.ErrorType // error_reporting level
+	displayErrors  bool              // Write reported errors to the output
+	errorHandler   *errorHandler     // Current set_error_handler() handler
+	errorHandlers  []*errorHandler   // Handlers replaced by set_error_handler()
+	lastError      *errorRecord      // Last error, for error_get_last()
+	errorListener  ErrorListener     // Observer of the reported errors
+	pendingError   error             // Exception thrown by an error handler, raised after the instruction
+
+	// Output buffering and the shutdown sequence (see output.go, shutdown.go)
+	outputBuffers     []*outputBuffer    // ob_start() levels, innermost last
+	shutdownFunctions []shutdownFunction // register_shutdown_function() callbacks
+	destructibles     []*types.Object    // Objects whose class has a destructor, in creation order
+	shutDown          bool               // Whether the shutdown sequence has run
+	exitStatus        int                // Process exit status (see ExitStatus)
+	result            *types.Value       // Value the main script returned (see Result)
+
+	// Magic property methods being called, against recursion (see isset.go)
+	magicCalls map[magicCall]bool
+
+	// Global constants, defined by const and define() (see constants.go)
+	definedConstants map[string]*types.Value
+
+	// declare(ticks=N) (see ticks.go)
+	tickCount     int             // Statements executed since the last tick
+	tickFunctions []*tickFunction // register_tick_function() callbacks
+
+	// Request data of the INPUT_* sources, set by the SAPI layer (see builtins_filter.go)
+	requestInput map[int64]*types.Array
+
+	// Session of the request, and the response headers and status code for
+	// the SAPI layer with where the output locking them started (see
+	// builtins_session.go, builtins_header.go)
+	session     *session.Session
+	response     header.Response
+	outputStart  *outputStart
+	outputWriter io.Writer // Receives the output instead of the buffer when set
+
+	// ini configuration and the limits it sets (see builtins_ini.go, limits.go)
+	config           *runtime.Config
+	timeLimit        time.Duration // max_execution_time
+	deadline         time.Time     // End of the execution time (zero if unlimited)
+	memoryLimit      int64         // memory_limit in bytes (0 if unlimited)
+	peakMemory       int64         // Highest memory usage sampled
+	instructionCount uint64        // Instructions executed, for the periodic checks
+	ctx              context.Context // Context of an embedded execution, checked with the limits
+
+	// Cycle collector (see gc.go)
+	gc *gcState
+
+	// Constant tables converted to values, by their first entry (see
+	// dispatch.go)
+	decodedConstants map[*interface{}]*constantValues
+
+	// Frames of finished calls, reused by the next calls (see
+	// newCallFrame)
+	freeFrames []*Frame
+
+	// Argument lists of finished calls, reused by the next calls (see
+	// newCallParams)
+	freeParams []*CallParams
+
+	// Program whose request the VM runs, nil for a standalone VM (see
+	// program.go)
+	program *Program
 }
 
 // CompiledFunction represents a compiled PHP function
 type CompiledFunction struct {
 	Name         string
 	Instructions Instructions
-	NumLocals    int // Number of local variables
-	NumParams    int // Number of parameters
+	Constants    []interface{}           // Constant table of the op array (nil: the VM's constant pool)
+	NumLocals    int                     // Number of local variables
+	NumParams    int                     // Number of parameters
+	TryCatch     []types.TryCatchElement // Exception table (try/catch/finally regions)
+	Variables    []string                // Compiled variable names by CV index
+	Parameters   []*types.ParameterDef   // Parameter definitions, for named arguments (nil if unknown)
+	Attributes   []*types.Attribute      // Attributes of a declared function
+	ReturnType   string                  // Declared return type ("" if none)
+	ReturnByRef  bool                    // Declared as function &name()
+	DocComment   string                  // /** */ comment preceding the declaration
+	StrictTypes  bool                    // Compiled in a declare(strict_types=1) file
+	File         string                  // Path of the file declaring it ("" if unknown)
+}
+
+// tempSlot returns the local slot of temporary n. The temporaries follow
+// the compiled variables, or the parameters when the variable names are
+// not known.
+func (fn *CompiledFunction) tempSlot(n uint32) int {
+	return max(len(fn.Variables), fn.NumParams) + int(n)
 }
 
 // Closure represents a PHP closure/anonymous function with captured variables
 type Closure struct {
 	Function        *CompiledFunction
+	Builtin         BuiltinFunction          // Set when wrapping a builtin (strlen(...))
 	CapturedVars    map[string]*types.Value // Variables from use clause
 	Static          bool                     // static closure (no $this access)
 	ReturnByRef     bool                     // Returns by reference
+	This            *types.Object            // Bound $this
+	Scope           *types.ClassEntry        // Class scope (self::)
+	CalledClass     *types.ClassEntry        // Called class (static::)
+	StaticVars      map[string]*types.Value  // Static variables of this closure instance
+	Fake            bool                     // Created from a function or method (Closure::fromCallable, strlen(...))
 }
 
 // CompiledClass is deprecated - use types.ClassEntry instead
@@ -54,16 +198,76 @@ type CompiledClass = types.ClassEntry
 
 // New creates a new virtual machine
 func New() *VM {
-	return &VM{
+	vm := &VM{
 		constants:     make([]interface{}, 0),
 		globals:       make(map[string]*types.Value),
 		functions:     make(map[string]*CompiledFunction),
+		builtins:      make(map[string]BuiltinFunction),
+		signatures:    make(map[string]*builtinSignature),
 		classes:       make(map[string]*CompiledClass),
 		frames:        make([]*Frame, 1024), // Pre-allocate frame stack
 		frameIndex:    -1,                   // -1 means no frames on stack
 		output:        make([]byte, 0),
 		maxStackDepth: 1000,
+		includePath:   []string{"."},
+		includedFiles: make(map[string]bool),
+		scriptCache:   newScriptCache(),
+
+		autoloading:        make(map[string]bool),
+		autoloadExtensions: []string{".inc", ".php"},
+
+		staticVars: make(map[*CompiledFunction]map[string]*types.Value),
+
+		definedConstants: runtime.BuiltinConstants(),
+
+		errorReporting: runtime.E_ALL,
+		displayErrors:  true,
+
+		config: runtime.NewConfig(),
+		gc:     newGCState(),
 	}
+	vm.registerExceptionClasses()
+	vm.registerEnumInterfaces()
+	vm.registerCallableBuiltins()
+	vm.registerClassBuiltins()
+	vm.registerDumpBuiltins()
+	vm.registerArrayBuiltins()
+	vm.registerIncludeBuiltins()
+	vm.registerAutoloadBuiltins()
+	vm.registerParallelBuiltins()
+	vm.registerPcreBuiltins()
+	vm.registerJsonBuiltins()
+	vm.registerPdoClasses()
+	vm.registerFileBuiltins()
+	vm.registerResourceBuiltins()
+	vm.registerVartypeBuiltins()
+	vm.registerStringBuiltins()
+	vm.registerMbstringBuiltins()
+	vm.registerCtypeBuiltins()
+	vm.registerFilterBuiltins()
+	vm.registerSessionBuiltins()
+	vm.registerHeaderBuiltins()
+	vm.registerCurlBuiltins()
+	vm.registerProcessBuiltins()
+	vm.registerDatetimeBuiltins()
+	vm.registerMathBuiltins()
+	vm.registerBcmathBuiltins()
+	vm.registerHashBuiltins()
+	vm.registerErrorBuiltins()
+	vm.registerOutputBuiltins()
+	vm.registerShutdownBuiltins()
+	vm.registerTickBuiltins()
+	vm.registerConstantBuiltins()
+	vm.registerReflectionBuiltins()
+	vm.registerIniBuiltins()
+	vm.registerLimitBuiltins()
+	vm.registerGCBuiltins()
+	vm.registerExtensionBuiltins()
+	vm.registerCoverageBuiltins()
+	vm.declareCoreSignatures()
+	vm.bindConfig()
+	vm.loadExtensions()
+	return vm
 }
 
 // NewWithBytecode creates a new VM and loads the bytecode
@@ -124,9 +328,11 @@ func (vm *VM) run() error {
 		instr := frame.fn.Instructions[frame.ip]
 		frame.ip++
 
-		// Dispatch instruction
+		// Dispatch instruction, unwinding to a catch/finally block on exceptions
 		if err := vm.dispatch(frame, instr); err != nil {
-			return err
+			if !vm.handleException(frame, err) {
+				return err
+			}
 		}
 	}
 
@@ -140,9 +346,11 @@ func (vm *VM) runFrame(frame *Frame) error {
 		instr := frame.fn.Instructions[frame.ip]
 		frame.ip++
 
-		// Dispatch instruction
+		// Dispatch instruction, unwinding to a catch/finally block on exceptions
 		if err := vm.dispatch(frame, instr); err != nil {
-			return err
+			if !vm.handleException(frame, err) {
+				return err
+			}
 		}
 	}
 
@@ -151,192 +359,38 @@ func (vm *VM) runFrame(frame *Frame) error {
 
 // dispatch executes a single instruction
 func (vm *VM) dispatch(frame *Frame, instr Instruction) error {
-	switch instr.Opcode {
-	// Arithmetic operations
-	case OpAdd:
-		return vm.opAdd(frame, instr)
-	case OpSub:
-		return vm.opSub(frame, instr)
-	case OpMul:
-		return vm.opMul(frame, instr)
-	case OpDiv:
-		return vm.opDiv(frame, instr)
-	case OpMod:
-		return vm.opMod(frame, instr)
-	case OpPow:
-		return vm.opPow(frame, instr)
-
-	// Comparison operations
-	case OpIsEqual:
-		return vm.opIsEqual(frame, instr)
-	case OpIsNotEqual:
-		return vm.opIsNotEqual(frame, instr)
-	case OpIsIdentical:
-		return vm.opIsIdentical(frame, instr)
-	case OpIsNotIdentical:
-		return vm.opIsNotIdentical(frame, instr)
-	case OpIsSmaller:
-		return vm.opIsSmaller(frame, instr)
-	case OpIsSmallerOrEqual:
-		return vm.opIsSmallerOrEqual(frame, instr)
-	case OpSpaceship:
-		return vm.opSpaceship(frame, instr)
-
-	// Bitwise operations
-	case OpBWAnd:
-		return vm.opBWAnd(frame, instr)
-	case OpBWOr:
-		return vm.opBWOr(frame, instr)
-	case OpBWXor:
-		return vm.opBWXor(frame, instr)
-	case OpBWNot:
-		return vm.opBWNot(frame, instr)
-	case OpSL:
-		return vm.opShiftLeft(frame, instr)
-	case OpSR:
-		return vm.opShiftRight(frame, instr)
-
-	// Logical operations
-	case OpBoolNot:
-		return vm.opBoolNot(frame, instr)
-
-	// Constants
-	case OpFetchConstant:
-		return vm.opConst(frame, instr)
-
-	// Variables
-	case OpAssign:
-		return vm.opAssign(frame, instr)
-	case OpFetchR:
-		return vm.opFetch(frame, instr)
-
-	// Control flow
-	case OpJmp:
-		return vm.opJmp(frame, instr)
-	case OpJmpZ:
-		return vm.opJmpZ(frame, instr)
-	case OpJmpNZ:
-		return vm.opJmpNZ(frame, instr)
-
-	// Functions
-	case OpReturn:
-		return vm.opReturn(frame, instr)
-	case OpInitFcall:
-		return vm.opInitFcall(frame, instr)
-	case OpSendVal:
-		return vm.opSendVal(frame, instr)
-	case OpDoFcall:
-		return vm.opDoFcall(frame, instr)
-	case OpDoUcall:
-		return vm.opDoUcall(frame, instr)
-	case OpDoIcall:
-		return vm.opDoIcall(frame, instr)
-
-	// I/O
-	case OpEcho:
-		return vm.opEcho(frame, instr)
-
-	// String operations
-	case OpConcat:
-		return vm.opConcat(frame, instr)
-	case OpFastConcat:
-		return vm.opFastConcat(frame, instr)
-
-	// Array operations
-	case OpInitArray:
-		return vm.opInitArray(frame, instr)
-	case OpAddArrayElement:
-		return vm.opAddArrayElement(frame, instr)
-	case OpFetchDimR:
-		return vm.opFetchDimR(frame, instr)
-	case OpFetchDimW:
-		return vm.opFetchDimW(frame, instr)
-	case OpFetchDimRW:
-		return vm.opFetchDimRW(frame, instr)
-	case OpFetchDimIs:
-		return vm.opFetchDimIs(frame, instr)
-	case OpFetchDimFuncArg:
-		return vm.opFetchDimFuncArg(frame, instr)
-	case OpFetchDimUnset:
-		return vm.opFetchDimUnset(frame, instr)
-	case OpAssignDim:
-		return vm.opAssignDim(frame, instr)
-	case OpAssignDimOp:
-		return vm.opAssignDimOp(frame, instr)
-	case OpUnsetDim:
-		return vm.opUnsetDim(frame, instr)
-	case OpIssetIsemptyDimObj:
-		return vm.opIssetIsemptyDimObj(frame, instr)
-	case OpCount:
-		return vm.opCount(frame, instr)
-	case OpInArray:
-		return vm.opInArray(frame, instr)
-	case OpArrayKeyExists:
-		return vm.opArrayKeyExists(frame, instr)
-
-	// Closure operations
-	case OpDeclareLambdaFunction:
-		return vm.opDeclareLambdaFunction(frame, instr)
-	case OpBindLexical:
-		return vm.opBindLexical(frame, instr)
-
-	// Object property operations - Fetch
-	case OpFetchObjR:
-		return vm.opFetchObjR(frame, instr)
-	case OpFetchObjW:
-		return vm.opFetchObjW(frame, instr)
-	case OpFetchObjRW:
-		return vm.opFetchObjRW(frame, instr)
-	case OpFetchObjIs:
-		return vm.opFetchObjIs(frame, instr)
-	case OpFetchObjFuncArg:
-		return vm.opFetchObjFuncArg(frame, instr)
-	case OpFetchObjUnset:
-		return vm.opFetchObjUnset(frame, instr)
-
-	// Object property operations - Assignment
-	case OpAssignObj:
-		return vm.opAssignObj(frame, instr)
-	case OpAssignObjOp:
-		return vm.opAssignObjOp(frame, instr)
-	case OpAssignObjRef:
-		return vm.opAssignObjRef(frame, instr)
-
-	// Object property operations - Unset/Isset
-	case OpUnsetObj:
-		return vm.opUnsetObj(frame, instr)
-	case OpIssetIsemptyPropObj:
-		return vm.opIssetIsemptyPropObj(frame, instr)
-
-	// Object property operations - Increment/Decrement
-	case OpPreIncObj:
-		return vm.opPreIncObj(frame, instr)
-	case OpPreDecObj:
-		return vm.opPreDecObj(frame, instr)
-	case OpPostIncObj:
-		return vm.opPostIncObj(frame, instr)
-	case OpPostDecObj:
-		return vm.opPostDecObj(frame, instr)
-
-	// Object creation and method calls
-	case OpNew:
-		return vm.opNew(frame, instr)
-	case OpInitMethodCall:
-		return vm.opInitMethodCall(frame, instr)
-	case OpInitStaticMethodCall:
-		return vm.opInitStaticMethodCall(frame, instr)
-	case OpClone:
-		return vm.opClone(frame, instr)
-	case OpInstanceof:
-		return vm.opInstanceof(frame, instr)
-	case OpGetClass:
-		return vm.opGetClass(frame, instr)
-	case OpFetchThis:
-		return vm.opFetchThis(frame, instr)
-
-	default:
-		return fmt.Errorf("unknown opcode: %s", instr.Opcode)
+	if vm.debugger != nil {
+		if err := vm.debugger.check(frame, instr); err != nil {
+			return err
+		}
+	}
+	if vm.coverage != nil && vm.coverage.running {
+		vm.coverage.record(frame, instr)
+	}
+	var entry *TraceEntry
+	if vm.tracer != nil {
+		entry = vm.tracer.before(vm, frame, instr)
 	}
+	var err error
+	if vm.profiler != nil {
+		err = vm.profiler.record(frame, instr, vm.dispatchOpcode)
+	} else {
+		err = vm.dispatchOpcode(frame, instr)
+	}
+	if entry != nil {
+		vm.tracer.after(vm, frame, instr, entry, err)
+	}
+	if err == nil && vm.pendingError != nil {
+		err = vm.takePendingError()
+	}
+	if err == nil && vm.gc.roots.HasReleased() {
+		err = vm.destroyReleased()
+	}
+	vm.instructionCount++
+	if err == nil && vm.instructionCount%limitCheckInterval == 0 {
+		err = vm.periodicChecks()
+	}
+	return err
 }
 
 // ============================================================================
@@ -354,11 +408,18 @@ func (vm *VM) currentFrame() *Frame {
 // pushFrame pushes a new frame onto the call stack
 func (vm *VM) pushFrame(frame *Frame) error {
 	if vm.frameIndex+1 >= vm.maxStackDepth {
-		return fmt.Errorf("stack overflow: maximum depth %d exceeded", vm.maxStackDepth)
+		return vm.ThrowError("Error", "Maximum function nesting level of '%d' reached, aborting!", vm.maxStackDepth)
 	}
 
 	vm.frameIndex++
+	if vm.frameIndex == len(vm.frames) {
+		// xdebug.max_nesting_level may exceed the frames allocated up front
+		vm.frames = append(vm.frames, make([]*Frame, len(vm.frames))...)
+	}
 	vm.frames[vm.frameIndex] = frame
+	if vm.callProfiler != nil {
+		vm.callProfiler.enterFrame(vm, frame)
+	}
 	return nil
 }
 
@@ -368,6 +429,9 @@ func (vm *VM) popFrame() *Frame {
 		return nil
 	}
 
+	if vm.callProfiler != nil {
+		vm.callProfiler.leave()
+	}
 	frame := vm.frames[vm.frameIndex]
 	vm.frames[vm.frameIndex] = nil // Clear reference
 	vm.frameIndex--
@@ -378,30 +442,118 @@ func (vm *VM) popFrame() *Frame {
 // Global Variables
 // ============================================================================
 
+// The global symbol table maps names to reference slots. Variables of the
+// main script and of "global $x" imports are bound to these slots, so every
+// scope sees the same live value.
+
 // SetGlobal sets a global variable
 func (vm *VM) SetGlobal(name string, value *types.Value) {
-	vm.globals[name] = value
+	vm.globalSlot(name).Assign(value)
 }
 
 // GetGlobal gets a global variable
 func (vm *VM) GetGlobal(name string) (*types.Value, bool) {
-	val, ok := vm.globals[name]
-	return val, ok
+	slot, ok := vm.globals[name]
+	if !ok || slot.Deref().IsUndef() {
+		return nil, false
+	}
+	return slot.Deref(), true
+}
+
+// globalSlot returns the reference slot of a global, creating it (undefined)
+// on first use
+func (vm *VM) globalSlot(name string) *types.Value {
+	slot, ok := vm.globals[name]
+	if !ok {
+		slot = types.NewReference(types.NewUndef())
+		vm.globals[name] = slot
+	}
+	return slot
+}
+
+// bindGlobalScope binds the compiled variables of a frame running in the
+// global scope (the main script, or a file it includes) to the globals
+func (vm *VM) bindGlobalScope(frame *Frame) {
+	frame.globalScope = true
+	for i, name := range frame.fn.Variables {
+		if i < len(frame.locals) {
+			frame.locals[i] = vm.globalSlot(name)
+		}
+	}
 }
 
 // ============================================================================
 // Functions
 // ============================================================================
 
-// RegisterFunction registers a compiled function
+// RegisterFunction registers a compiled function under its fully qualified
+// name. The name is interned like the names calls use to look it up.
 func (vm *VM) RegisterFunction(name string, fn *CompiledFunction) {
-	vm.functions[name] = fn
+	vm.functions[types.InternString(strings.TrimPrefix(name, "\\"))] = fn
 }
 
-// GetFunction gets a compiled function
+// GetFunction gets a compiled function by its fully qualified name, among
+// the functions declared in the VM, then those of its program
 func (vm *VM) GetFunction(name string) (*CompiledFunction, bool) {
-	fn, ok := vm.functions[name]
-	return fn, ok
+	name = strings.TrimPrefix(name, "\\")
+	if fn, ok := vm.functions[name]; ok {
+		return fn, true
+	}
+	if vm.program != nil {
+		fn, ok := vm.program.functions[name]
+		return fn, ok
+	}
+	return nil, false
+}
+
+// ============================================================================
+// Classes
+// ============================================================================
+
+// RegisterClass registers a class under its fully qualified name. Enums
+// get their case constants, interfaces and static methods.
+func (vm *VM) RegisterClass(class *CompiledClass) {
+	if class.IsEnum {
+		vm.initEnum(class)
+	}
+	vm.classes[types.InternString(strings.TrimPrefix(class.Name, "\\"))] = class
+}
+
+// DeclareClass registers a class after checking that, unless abstract, it
+// implements every abstract method it inherits or declares, including
+// those of its interfaces
+func (vm *VM) DeclareClass(class *CompiledClass) error {
+	if class.IsEnum {
+		vm.initEnum(class)
+	}
+	if err := class.ValidateAbstractMethods(); err != nil {
+		return err
+	}
+	vm.classes[types.InternString(strings.TrimPrefix(class.Name, "\\"))] = class
+	return nil
+}
+
+// lookupClass finds a class by name. A leading backslash is ignored and,
+// as class names are case-insensitive, a case-insensitive match is used
+// when there is no exact one.
+func (vm *VM) lookupClass(name string) (*CompiledClass, bool) {
+	name = strings.TrimPrefix(name, "\\")
+	if class, ok := vm.classes[name]; ok && class != nil {
+		return class, true
+	}
+	for registered, class := range vm.classes {
+		if class != nil && strings.EqualFold(registered, name) {
+			return class, true
+		}
+	}
+	return nil, false
+}
+
+// HasClass reports whether a class, interface, trait or enum of the name
+// is declared in the VM, without autoloading it
+func (vm *VM) HasClass(name string) bool {
+	_, ok := vm.lookupClass(name)
+	return ok
 }
 
 // ============================================================================
@@ -410,11 +562,36 @@ func (vm *VM) GetFunction(name string) (*CompiledFunction, bool) {
 
 // GetConstant retrieves a constant from the constant pool
 func (vm *VM) GetConstant(index int) (*types.Value, error) {
-	if index < 0 || index >= len(vm.constants) {
+	return constantValue(vm.constants, index)
+}
+
+// constantsOf returns the constant table the CONST operands of a frame's
+// instructions index: its op array's own, or the VM's constant pool for
+// functions built without one
+func (vm *VM) constantsOf(frame *Frame) []interface{} {
+	if frame != nil && frame.fn != nil && frame.fn.Constants != nil {
+		return frame.fn.Constants
+	}
+	return vm.constants
+}
+
+// constantOperand returns the constant table entry a CONST operand of a
+// frame's instruction references
+func (vm *VM) constantOperand(frame *Frame, op Operand) (interface{}, bool) {
+	constants := vm.constantsOf(frame)
+	if op.Type != OpConst || int(op.Value) >= len(constants) {
+		return nil, false
+	}
+	return constants[op.Value], true
+}
+
+// constantValue converts an entry of a constant table to a value
+func constantValue(constants []interface{}, index int) (*types.Value, error) {
+	if index < 0 || index >= len(constants) {
 		return nil, fmt.Errorf("constant index out of range: %d", index)
 	}
 
-	c := vm.constants[index]
+	c := constants[index]
 
 	// Convert to Value
 	switch v := c.(type) {
@@ -447,26 +624,41 @@ func (vm *VM) ClearOutput() {
 	vm.output = vm.output[:0]
 }
 
-// writeOutput writes to the output buffer
+// writeOutput writes to the innermost ob_start() buffer, or to the output
+// buffer if none is open
 func (vm *VM) writeOutput(data []byte) {
-	vm.output = append(vm.output, data...)
+	if n := len(vm.outputBuffers); n > 0 {
+		vm.outputBuffers[n-1].data = append(vm.outputBuffers[n-1].data, data...)
+		return
+	}
+	vm.emitOutput(data)
 }
 
 // ============================================================================
 // Helper Methods
 // ============================================================================
 
+// currentLine returns the source line of the instruction being executed
+func (vm *VM) currentLine() int {
+	frame := vm.currentFrame()
+	if frame == nil || frame.ip <= 0 || frame.ip > len(frame.fn.Instructions) {
+		return 0
+	}
+	return int(frame.fn.Instructions[frame.ip-1].Lineno)
+}
+
 // getOperandValue retrieves the value of an operand
 func (vm *VM) getOperandValue(frame *Frame, op Operand) (*types.Value, error) {
 	switch op.Type {
 	case OpConst:
-		return vm.GetConstant(int(op.Value))
+		return vm.constantOperandValue(frame, int(op.Value))
 	case OpVar, OpCV:
-		// Compiled variable (parameters are at the start of locals)
-		return frame.getLocal(int(op.Value)), nil
+		// Compiled variable (parameters are at the start of locals);
+		// variables bound to a reference read the referenced value
+		return frame.getLocal(int(op.Value)).Deref(), nil
 	case OpTmpVar:
-		// Temporary variable (starts after parameters to avoid conflicts)
-		return frame.getLocal(int(op.Value) + frame.fn.NumParams), nil
+		// Temporary variable (after the compiled variables)
+		return frame.getLocal(frame.fn.tempSlot(op.Value)), nil
 	case OpUnused:
 		return types.NewNull(), nil
 	default:
@@ -478,12 +670,16 @@ func (vm *VM) getOperandValue(frame *Frame, op Operand) (*types.Value, error) {
 func (vm *VM) setOperandValue(frame *Frame, op Operand, value *types.Value) error {
 	switch op.Type {
 	case OpVar, OpCV:
-		// Compiled variable (parameters)
+		// Compiled variable (parameters); assigning to a variable bound
+		// to a reference (static, global) writes through it
+		if frame.getLocal(int(op.Value)).Assign(value) {
+			return nil
+		}
 		frame.setLocal(int(op.Value), value)
 		return nil
 	case OpTmpVar:
-		// Temporary variable (starts after parameters)
-		frame.setLocal(int(op.Value)+frame.fn.NumParams, value)
+		// Temporary variable (after the compiled variables)
+		frame.setLocal(frame.fn.tempSlot(op.Value), value)
 		return nil
 	case OpUnused:
 		// Do nothing
@@ -497,108 +693,100 @@ func (vm *VM) setOperandValue(frame *Frame, op Operand, value *types.Value) erro
 // Closure Operations
 // ============================================================================
 
+// Flags of DECLARE_LAMBDA_FUNCTION
+const (
+	LambdaStatic = 1 << 0 // static function () {}
+	LambdaByRef  = 1 << 1 // function &() {}
+)
+
 // opDeclareLambdaFunction creates a closure object
-// ExtendedValue: number of parameters
-// Op1: flags (static, byref)
-// Op2: closure start position
-// Result: closure end position, closure object placed in temp var 0
+// ExtendedValue: flags (LambdaStatic, LambdaByRef)
+// Op1: the FunctionDecl constant holding the closure's op array
+// Result: the closure object
 func (vm *VM) opDeclareLambdaFunction(frame *Frame, instr Instruction) error {
-	numParams := int(instr.ExtendedValue)
-	flags, err := vm.getOperandValue(frame, instr.Op1)
-	if err != nil {
-		return err
-	}
-	startPos, err := vm.getOperandValue(frame, instr.Op2)
+	decl, err := vm.functionDecl(frame, instr.Op1)
 	if err != nil {
 		return err
 	}
-	endPos, err := vm.getOperandValue(frame, instr.Result)
-	if err != nil {
-		return err
-	}
-
-	flagsInt := int64(flags.ToInt())
-	isStatic := (flagsInt & 1) != 0
-	isByRef := (flagsInt & 2) != 0
+	isStatic := instr.ExtendedValue&LambdaStatic != 0
+	isByRef := instr.ExtendedValue&LambdaByRef != 0
 
-	// Extract instructions for the closure body
-	start := int(startPos.ToInt())
-	end := int(endPos.ToInt())
-	closureInstructions := frame.fn.Instructions[start:end]
-
-	// Create compiled function for the closure
-	compiledFunc := &CompiledFunction{
-		Name:         "<closure>",
-		Instructions: closureInstructions,
-		NumLocals:    100, // TODO: Calculate actual number of locals
-		NumParams:    numParams,
-	}
+	// Each closure object gets its own copy of the function
+	fn := *decl.Function
 
-	// Create closure object
-	_ = &Closure{
-		Function:     compiledFunc,
+	// Create closure object, bound to the declaring frame's $this and scope
+	closure := &Closure{
+		Function:     &fn,
 		CapturedVars: make(map[string]*types.Value),
+		StaticVars:   make(map[string]*types.Value),
 		Static:       isStatic,
 		ReturnByRef:  isByRef,
+		Scope:        frame.currentClass,
+		CalledClass:  frame.calledClass,
 	}
-
-	// TODO: Store closure properly once we have a proper closure value type
-	// For now, we'll store it as a PHP object with the closure embedded
-	obj := &types.Object{
-		ClassName:  "Closure",
-		Properties: map[string]*types.Property{
-			"__closure__": {
-				Value:      types.NewNull(), // Store actual closure here (hack for now)
-				Visibility: types.VisibilityPublic,
-			},
-		},
-		ObjectID:   0, // Will be assigned by nextObjectID()
-		IsDestroyed: false,
+	if !isStatic {
+		closure.This = frame.thisObject
 	}
-	closureValue := types.NewObject(obj)
-	frame.setLocal(0, closureValue) // Store in temp var 0
 
-	return nil
+	return vm.setOperandValue(frame, instr.Result, newClosureObject(closure))
 }
 
-// opBindLexical binds a captured variable to a closure
-// Op1: variable name (constant index)
-// Op2: by-reference flag (0 = by value, 1 = by reference)
+// Flags of BIND_LEXICAL
+const (
+	BindLexicalRef      = 1 << 0 // use (&$x)
+	BindLexicalImplicit = 1 << 1 // Captured by an arrow function
+)
+
+// opBindLexical captures a variable of the declaring scope in a closure:
+// a copy of its value, or the variable itself bound to a reference
+// ExtendedValue: flags (BindLexicalRef, BindLexicalImplicit)
+// Op1: the variable
+// Op2: variable name (constant)
 // Result: closure object (in temp var)
 func (vm *VM) opBindLexical(frame *Frame, instr Instruction) error {
-	// Get variable name from constants
-	varNameConst, err := vm.getOperandValue(frame, instr.Op1)
-	if err != nil {
-		return err
-	}
-	varName := varNameConst.ToString()
-
-	// Get by-reference flag
-	byRefFlag, err := vm.getOperandValue(frame, instr.Op2)
+	name, err := vm.getOperandValue(frame, instr.Op2)
 	if err != nil {
 		return err
 	}
-	isByRef := byRefFlag.ToInt() != 0
-
-	// Get closure object from Result operand
 	closureValue, err := vm.getOperandValue(frame, instr.Result)
 	if err != nil {
 		return err
 	}
+	closure, ok := closureValue.ToObject().Internal.(*Closure)
+	if !ok {
+		return fmt.Errorf("BIND_LEXICAL on a non-closure")
+	}
 
-	// For now, we'll just store a placeholder
-	// TODO: Implement proper closure variable binding
-	// This requires accessing the parent frame's variables by name
-	// and storing them in the closure's captured variables map
-
-	// Create a placeholder captured variable
-	varValue := types.NewNull()
-
-	// Log binding for debugging (could be removed in production)
-	_ = varName
-	_ = isByRef
-	_ = closureValue
-	_ = varValue
-
+	var value *types.Value
+	if instr.ExtendedValue&BindLexicalRef != 0 {
+		if value, err = vm.variableReference(frame, instr.Op1); err != nil {
+			return err
+		}
+	} else {
+		value = frame.getLocal(int(instr.Op1.Value)).Deref()
+		if value.IsUndef() {
+			if instr.ExtendedValue&BindLexicalImplicit != 0 {
+				return nil // Arrow functions capture only defined variables
+			}
+			vm.warning("Undefined variable $%s", name.ToString())
+			value = types.NewNull()
+		}
+		value = assignValue(value)
+	}
+	closure.CapturedVars[name.ToString()] = value
 	return nil
 }
+
+// bindCapturedVars gives the variables a closure captured to the frame
+// running it: each call starts from the captured values, while variables
+// captured by reference stay bound to the declaring scope's
+func bindCapturedVars(frame *Frame, captured map[string]*types.Value) {
+	for name, value := range captured {
+		if i := frame.variableIndex(name); i >= 0 {
+			if !value.IsReference() {
+				value = assignValue(value)
+			}
+			frame.setLocal(i, value)
+		}
+	}
+}
@@ -262,7 +262,7 @@ func TestExecute_SimpleReturn(t *testing.T) {
 	vm.constants = []interface{}{int64(42)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).
+		*NewInstruction(OpQMAssign, 1).
 			WithOp1(OpConst, 0).
 			WithResult(OpCV, 0),
 		*NewInstruction(OpReturn, 2).
@@ -282,11 +282,11 @@ func TestExecute_Arithmetic(t *testing.T) {
 	// Calculate: 10 + 5
 	instructions := Instructions{
 		// Load 10 into CV0
-		*NewInstruction(OpFetchConstant, 1).
+		*NewInstruction(OpQMAssign, 1).
 			WithOp1(OpConst, 0).
 			WithResult(OpCV, 0),
 		// Load 5 into CV1
-		*NewInstruction(OpFetchConstant, 2).
+		*NewInstruction(OpQMAssign, 2).
 			WithOp1(OpConst, 1).
 			WithResult(OpCV, 1),
 		// Add CV0 + CV1 -> CV2
@@ -314,7 +314,7 @@ func TestExecute_Echo(t *testing.T) {
 
 	instructions := Instructions{
 		// Load string constant
-		*NewInstruction(OpFetchConstant, 1).
+		*NewInstruction(OpQMAssign, 1).
 			WithOp1(OpConst, 0).
 			WithResult(OpCV, 0),
 		// Echo it
@@ -336,16 +336,46 @@ func TestExecute_Echo(t *testing.T) {
 	}
 }
 
+func TestExecute_FastConcat(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"foo", "bar", int64(7)}
+
+	instructions := Instructions{
+		*NewInstruction(OpFastConcat, 1).
+			WithOp1(OpConst, 0).
+			WithOp2(OpConst, 1).
+			WithResult(OpCV, 0),
+		*NewInstruction(OpEcho, 2).
+			WithOp1(OpCV, 0),
+		// Operands that are not strings take the CONCAT path
+		*NewInstruction(OpFastConcat, 3).
+			WithOp1(OpConst, 0).
+			WithOp2(OpConst, 2).
+			WithResult(OpCV, 1),
+		*NewInstruction(OpEcho, 4).
+			WithOp1(OpCV, 1),
+		*NewInstruction(OpReturn, 5).
+			WithOp1(OpUnused, 0),
+	}
+
+	if err := vm.Execute(instructions); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if output := vm.GetOutput(); output != "foobarfoo7" {
+		t.Errorf("Expected 'foobarfoo7', got '%s'", output)
+	}
+}
+
 func TestExecute_Comparison(t *testing.T) {
 	vm := New()
 	vm.constants = []interface{}{int64(5), int64(5)}
 
 	// Test 5 == 5
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).
+		*NewInstruction(OpQMAssign, 1).
 			WithOp1(OpConst, 0).
 			WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).
+		*NewInstruction(OpQMAssign, 2).
 			WithOp1(OpConst, 1).
 			WithResult(OpCV, 1),
 		*NewInstruction(OpIsEqual, 3).
@@ -401,8 +431,8 @@ func TestExecute_Sub(t *testing.T) {
 	vm.constants = []interface{}{int64(10), int64(3)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpSub, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -418,8 +448,8 @@ func TestExecute_Mul(t *testing.T) {
 	vm.constants = []interface{}{int64(6), int64(7)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpMul, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -435,8 +465,8 @@ func TestExecute_Div(t *testing.T) {
 	vm.constants = []interface{}{int64(20), int64(4)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpDiv, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -452,8 +482,8 @@ func TestExecute_Mod(t *testing.T) {
 	vm.constants = []interface{}{int64(17), int64(5)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpMod, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -469,8 +499,8 @@ func TestExecute_Pow(t *testing.T) {
 	vm.constants = []interface{}{int64(2), int64(8)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpPow, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -486,8 +516,8 @@ func TestExecute_ArithmeticFloats(t *testing.T) {
 	vm.constants = []interface{}{3.14, 2.0}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpAdd, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -503,8 +533,8 @@ func TestExecute_DivisionByZero(t *testing.T) {
 	vm.constants = []interface{}{int64(10), int64(0)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpDiv, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -520,8 +550,8 @@ func TestExecute_ModByZero(t *testing.T) {
 	vm.constants = []interface{}{int64(10), int64(0)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpMod, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -538,8 +568,8 @@ func TestExecute_MixedTypeArithmetic(t *testing.T) {
 
 	// Test int + float
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpSub, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -555,8 +585,8 @@ func TestExecute_MultiplyFloats(t *testing.T) {
 	vm.constants = []interface{}{2.5, 4.0}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpMul, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -576,8 +606,8 @@ func TestExecute_IsNotEqual(t *testing.T) {
 	vm.constants = []interface{}{int64(5), int64(3)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpIsNotEqual, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -593,8 +623,8 @@ func TestExecute_IsIdentical(t *testing.T) {
 	vm.constants = []interface{}{int64(5), int64(5)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpIsIdentical, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -610,8 +640,8 @@ func TestExecute_IsNotIdentical(t *testing.T) {
 	vm.constants = []interface{}{int64(5), "5"}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpIsNotIdentical, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -627,8 +657,8 @@ func TestExecute_IsSmaller(t *testing.T) {
 	vm.constants = []interface{}{int64(3), int64(5)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpIsSmaller, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -644,8 +674,8 @@ func TestExecute_IsSmallerOrEqual(t *testing.T) {
 	vm.constants = []interface{}{int64(5), int64(5)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpIsSmallerOrEqual, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -661,8 +691,8 @@ func TestExecute_Spaceship(t *testing.T) {
 	vm.constants = []interface{}{int64(3), int64(5)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpSpaceship, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -678,8 +708,8 @@ func TestExecute_Spaceship_Equal(t *testing.T) {
 	vm.constants = []interface{}{int64(5), int64(5)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpSpaceship, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -695,8 +725,8 @@ func TestExecute_Spaceship_Greater(t *testing.T) {
 	vm.constants = []interface{}{int64(10), int64(3)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpSpaceship, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -712,8 +742,8 @@ func TestExecute_ComparisonFloats(t *testing.T) {
 	vm.constants = []interface{}{3.5, 2.5}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpIsSmaller, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -733,7 +763,7 @@ func TestExecute_BoolNot(t *testing.T) {
 	vm.constants = []interface{}{true}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
 		*NewInstruction(OpBoolNot, 2).WithOp1(OpCV, 0).WithResult(OpCV, 1),
 		*NewInstruction(OpReturn, 3).WithOp1(OpCV, 1),
 	}
@@ -749,7 +779,7 @@ func TestExecute_BWNot(t *testing.T) {
 	vm.constants = []interface{}{int64(5)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
 		*NewInstruction(OpBWNot, 2).WithOp1(OpCV, 0).WithResult(OpCV, 1),
 		*NewInstruction(OpReturn, 3).WithOp1(OpCV, 1),
 	}
@@ -765,8 +795,8 @@ func TestExecute_BWAnd(t *testing.T) {
 	vm.constants = []interface{}{int64(12), int64(10)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpBWAnd, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -782,8 +812,8 @@ func TestExecute_BWOr(t *testing.T) {
 	vm.constants = []interface{}{int64(12), int64(10)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpBWOr, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -799,8 +829,8 @@ func TestExecute_BWXor(t *testing.T) {
 	vm.constants = []interface{}{int64(12), int64(10)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpBWXor, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -816,8 +846,8 @@ func TestExecute_ShiftLeft(t *testing.T) {
 	vm.constants = []interface{}{int64(5), int64(2)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpSL, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -833,8 +863,8 @@ func TestExecute_ShiftRight(t *testing.T) {
 	vm.constants = []interface{}{int64(20), int64(2)}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpSR, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -854,8 +884,8 @@ func TestExecute_Concat(t *testing.T) {
 	vm.constants = []interface{}{"Hello, ", "World!"}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
-		*NewInstruction(OpFetchConstant, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 2).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpConcat, 3).WithOp1(OpCV, 0).WithOp2(OpCV, 1).WithResult(OpCV, 2),
 		*NewInstruction(OpReturn, 4).WithOp1(OpCV, 2),
 	}
@@ -866,6 +896,32 @@ func TestExecute_Concat(t *testing.T) {
 	}
 }
 
+func TestExecute_Rope(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{"Hello ", int64(42), "!", 1.5}
+
+	// "Hello {$n}!" and "$f"
+	instructions := Instructions{
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 1).WithResult(OpCV, 0),
+		*NewInstruction(OpRopeInit, 2).WithOp2(OpConst, 0).WithResult(OpTmpVar, 8).WithExtended(3),
+		*NewInstruction(OpRopeAdd, 2).WithOp1(OpTmpVar, 8).WithOp2(OpCV, 0).WithResult(OpTmpVar, 8).WithExtended(1),
+		*NewInstruction(OpRopeEnd, 2).WithOp1(OpTmpVar, 8).WithOp2(OpConst, 2).WithResult(OpTmpVar, 5).WithExtended(2),
+		*NewInstruction(OpEcho, 2).WithOp1(OpTmpVar, 5),
+		*NewInstruction(OpRopeInit, 3).WithOp2(OpConst, 3).WithResult(OpTmpVar, 8).WithExtended(1),
+		*NewInstruction(OpRopeEnd, 3).WithOp1(OpTmpVar, 8).WithResult(OpTmpVar, 5).WithExtended(1),
+		*NewInstruction(OpEcho, 3).WithOp1(OpTmpVar, 5),
+		*NewInstruction(OpReturn, 4).WithOp1(OpUnused, 0),
+	}
+
+	if err := vm.Execute(instructions); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+
+	if output := vm.GetOutput(); output != "Hello 42!1.5" {
+		t.Errorf("Expected 'Hello 42!1.5', got '%s'", output)
+	}
+}
+
 // ============================================================================
 // Control Flow Opcode Tests
 // ============================================================================
@@ -875,11 +931,11 @@ func TestExecute_JmpZ(t *testing.T) {
 	vm.constants = []interface{}{false, "Skipped", "Executed"}
 
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0),
 		*NewInstruction(OpJmpZ, 2).WithOp1(OpCV, 0).WithOp2(OpConst, 4),
-		*NewInstruction(OpFetchConstant, 3).WithOp1(OpConst, 1).WithResult(OpCV, 1),
+		*NewInstruction(OpQMAssign, 3).WithOp1(OpConst, 1).WithResult(OpCV, 1),
 		*NewInstruction(OpEcho, 4).WithOp1(OpCV, 1),
-		*NewInstruction(OpFetchConstant, 5).WithOp1(OpConst, 2).WithResult(OpCV, 2),
+		*NewInstruction(OpQMAssign, 5).WithOp1(OpConst, 2).WithResult(OpCV, 2),
 		*NewInstruction(OpEcho, 6).WithOp1(OpCV, 2),
 		*NewInstruction(OpReturn, 7).WithOp1(OpUnused, 0),
 	}
@@ -901,10 +957,10 @@ func TestExecute_JmpNZ(t *testing.T) {
 
 	// Test: if (true) { jump to echo }
 	instructions := Instructions{
-		*NewInstruction(OpFetchConstant, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0), // 0: Load true
+		*NewInstruction(OpQMAssign, 1).WithOp1(OpConst, 0).WithResult(OpCV, 0), // 0: Load true
 		*NewInstruction(OpJmpNZ, 2).WithOp1(OpCV, 0).WithOp2(OpConst, 3),           // 1: If true, jump to 3
 		*NewInstruction(OpReturn, 3).WithOp1(OpUnused, 0),                          // 2: Return (skipped)
-		*NewInstruction(OpFetchConstant, 4).WithOp1(OpConst, 1).WithResult(OpCV, 1), // 3: Load "Executed"
+		*NewInstruction(OpQMAssign, 4).WithOp1(OpConst, 1).WithResult(OpCV, 1), // 3: Load "Executed"
 		*NewInstruction(OpEcho, 5).WithOp1(OpCV, 1),                                // 4: Echo "Executed"
 		*NewInstruction(OpReturn, 6).WithOp1(OpUnused, 0),                          // 5: Return
 	}
@@ -919,3 +975,28 @@ func TestExecute_JmpNZ(t *testing.T) {
 		t.Errorf("Expected 'Executed', got '%s'", output)
 	}
 }
+
+func TestAssign_ArraysHaveValueSemantics(t *testing.T) {
+	vm := New()
+	vm.constants = []interface{}{int64(1), int64(2)}
+
+	// $a = [1]; $b = $a; $b[] = 2;
+	instrs := Instructions{
+		{Opcode: OpInitArray, Result: Operand{Type: OpCV, Value: 0}},
+		{Opcode: OpAddArrayElement, Op1: Operand{Type: OpConst, Value: 0}, Result: Operand{Type: OpCV, Value: 0}},
+		{Opcode: OpAssign, Op2: Operand{Type: OpCV, Value: 0}, Result: Operand{Type: OpCV, Value: 1}},
+		{Opcode: OpAssignDim, Op1: Operand{Type: OpCV, Value: 1}, Result: Operand{Type: OpConst, Value: 1}},
+	}
+	frame := NewFrame(&CompiledFunction{Name: "main", Instructions: instrs, NumLocals: 10})
+	vm.pushFrame(frame)
+	if err := vm.runFrame(frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if a := frame.getLocal(0).ToArray(); a.Len() != 1 {
+		t.Errorf("Expected $a to keep 1 element, got %s", a)
+	}
+	if b := frame.getLocal(1).ToArray(); b.Len() != 2 {
+		t.Errorf("Expected $b to have 2 elements, got %s", b)
+	}
+}